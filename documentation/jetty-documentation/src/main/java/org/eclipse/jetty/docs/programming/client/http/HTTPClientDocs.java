@@ -28,6 +28,7 @@ import java.util.concurrent.TimeUnit;
 import java.util.function.LongConsumer;
 
 import org.eclipse.jetty.client.ConnectionPool;
+import org.eclipse.jetty.client.ConnectionPoolInspector;
 import org.eclipse.jetty.client.HttpClient;
 import org.eclipse.jetty.client.HttpClientTransport;
 import org.eclipse.jetty.client.HttpDestination;
@@ -900,6 +901,32 @@ public class HTTPClientDocs
         // end::setConnectionPool[]
     }
 
+    public void inspectConnectionPool() throws Exception
+    {
+        // tag::inspectConnectionPool[]
+        HttpClient httpClient = new HttpClient();
+        ConnectionPoolInspector inspector = new ConnectionPoolInspector(httpClient);
+        // Add the inspector as a bean to export it via JMX.
+        httpClient.addBean(inspector);
+        httpClient.start();
+
+        for (ConnectionPoolInspector.DestinationInfo destination : inspector.getDestinations())
+        {
+            System.getLogger("inspector").log(INFO, "{0} has {1} queued requests",
+                destination.getOrigin(), destination.getQueuedRequests().size());
+            for (ConnectionPoolInspector.ConnectionInfo connection : destination.getConnections())
+            {
+                System.getLogger("inspector").log(INFO, "{0} {1} active={2} idle={3}ms",
+                    connection.getId(), connection.getProtocol(), connection.getActiveRequests(), connection.getIdleTime());
+            }
+        }
+
+        // Quarantine the connections that have been opened more than 1 hour ago:
+        // they are closed as soon as the requests in progress on them complete.
+        inspector.quarantine(connection -> connection.getAge() > TimeUnit.HOURS.toMillis(1));
+        // end::inspectConnectionPool[]
+    }
+
     public void unixDomain() throws Exception
     {
         // tag::unixDomain[]
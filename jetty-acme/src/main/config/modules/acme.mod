@@ -0,0 +1,53 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Provisions and renews the TLS certificate of the server via the
+ACME protocol (for example from Let's Encrypt), validating the
+domains with http-01 challenges on the clear-text connector.
+The certificate is hot reloaded when renewed.
+
+[tags]
+connector
+ssl
+
+[depend]
+ssl
+client
+
+[xml]
+etc/jetty-acme.xml
+
+[lib]
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/jetty-acme-${jetty.version}.jar
+
+[ini-template]
+## The ACME server directory URI.
+# jetty.acme.directoryURI=https://acme-v02.api.letsencrypt.org/directory
+
+## The comma separated certificate domains, the first being the subject common name.
+# jetty.acme.domains=
+
+## The ACME account contact email.
+# jetty.acme.email=
+
+## Whether the terms of service of the ACME server are agreed; must be true.
+# jetty.acme.termsOfServiceAgreed=false
+
+## The ACME account key file, relative to $JETTY_BASE.
+# jetty.acme.accountKeyPath=etc/acme-account.pem
+
+## The key store file of the certificate, relative to $JETTY_BASE.
+# jetty.acme.keyStorePath=etc/acme-keystore.p12
+
+## The key store password.
+# jetty.acme.keyStorePassword=
+
+## The number of days before the certificate expiration at which the certificate is renewed.
+# jetty.acme.renewBeforeDays=30
+
+## The interval in milliseconds between certificate checks.
+# jetty.acme.checkInterval=43200000
+
+## The interval in milliseconds before a failed certificate renewal is retried.
+# jetty.acme.retryInterval=3600000
@@ -0,0 +1,358 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.net.URI;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.security.KeyPair;
+import java.security.cert.X509Certificate;
+import java.time.Duration;
+import java.time.Instant;
+import java.util.Arrays;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Provisions and renews, via the ACME protocol, the certificate used by a {@link SslContextFactory.Server}.</p>
+ * <p>The certificate and its private key are stored in a PKCS12 {@link #setKeyStorePath(String) key store}
+ * that is configured as the key store of the {@code SslContextFactory}; until a certificate
+ * is issued, a temporary self-signed certificate is used, so that the TLS connectors can start.</p>
+ * <p>The certificate is checked periodically, and renewed when it is about to
+ * {@link #setRenewBeforeDays(int) expire}; the new certificate is then hot reloaded
+ * by the {@code SslContextFactory}, without interrupting the existing connections.</p>
+ * <p>The domains are validated by an {@link AcmeChallengeResponder}, either an
+ * {@link AcmeChallengeHandler} for {@code http-01} challenges, or an
+ * {@link AcmeSslContextFactory} for {@code tls-alpn-01} challenges.</p>
+ * <p>The account key pair is stored in the {@link #setAccountKeyPath(String) account key file},
+ * and generated if the file does not exist.</p>
+ */
+@ManagedObject("ACME certificate manager")
+public class AcmeCertificateManager extends ContainerLifeCycle
+{
+    public static final String LETS_ENCRYPT_DIRECTORY_URI = "https://acme-v02.api.letsencrypt.org/directory";
+    public static final String LETS_ENCRYPT_STAGING_DIRECTORY_URI = "https://acme-staging-v02.api.letsencrypt.org/directory";
+    private static final Logger LOG = LoggerFactory.getLogger(AcmeCertificateManager.class);
+    private static final String ALIAS = "acme";
+
+    private final AutoLock lock = new AutoLock();
+    private final SslContextFactory.Server sslContextFactory;
+    private final AcmeChallengeResponder challengeResponder;
+    private HttpClient httpClient;
+    private Scheduler scheduler;
+    private Scheduler.Task task;
+    private String directoryURI = LETS_ENCRYPT_DIRECTORY_URI;
+    private List<String> domains = List.of();
+    private String email;
+    private boolean termsOfServiceAgreed;
+    private String accountKeyPath = "acme-account.pem";
+    private String keyStorePath = "acme-keystore.p12";
+    private String keyStorePassword;
+    private int renewBeforeDays = 30;
+    private long checkInterval = TimeUnit.HOURS.toMillis(12);
+    private long retryInterval = TimeUnit.HOURS.toMillis(1);
+    private volatile Instant notAfter;
+
+    public AcmeCertificateManager(@Name("sslContextFactory") SslContextFactory.Server sslContextFactory, @Name("challengeResponder") AcmeChallengeResponder challengeResponder)
+    {
+        this.sslContextFactory = Objects.requireNonNull(sslContextFactory);
+        this.challengeResponder = Objects.requireNonNull(challengeResponder);
+    }
+
+    public SslContextFactory.Server getSslContextFactory()
+    {
+        return sslContextFactory;
+    }
+
+    public AcmeChallengeResponder getChallengeResponder()
+    {
+        return challengeResponder;
+    }
+
+    public HttpClient getHttpClient()
+    {
+        return httpClient;
+    }
+
+    /**
+     * @param httpClient the HttpClient used to talk to the ACME server,
+     * or null to use a default one
+     */
+    public void setHttpClient(HttpClient httpClient)
+    {
+        updateBean(this.httpClient, httpClient);
+        this.httpClient = httpClient;
+    }
+
+    @ManagedAttribute("The ACME server directory URI")
+    public String getDirectoryURI()
+    {
+        return directoryURI;
+    }
+
+    public void setDirectoryURI(String directoryURI)
+    {
+        this.directoryURI = directoryURI;
+    }
+
+    @ManagedAttribute("The certificate domains")
+    public String[] getDomains()
+    {
+        return domains.toArray(new String[0]);
+    }
+
+    /**
+     * @param domains the certificate domains, the first being the subject common name
+     */
+    public void setDomains(String... domains)
+    {
+        this.domains = List.copyOf(Arrays.asList(domains));
+    }
+
+    @ManagedAttribute("The ACME account contact email")
+    public String getEmail()
+    {
+        return email;
+    }
+
+    public void setEmail(String email)
+    {
+        this.email = email;
+    }
+
+    @ManagedAttribute("Whether the terms of service of the ACME server have been agreed")
+    public boolean isTermsOfServiceAgreed()
+    {
+        return termsOfServiceAgreed;
+    }
+
+    /**
+     * @param termsOfServiceAgreed whether the terms of service of the ACME server have been
+     * agreed; must be true for the ACME account to be created
+     */
+    public void setTermsOfServiceAgreed(boolean termsOfServiceAgreed)
+    {
+        this.termsOfServiceAgreed = termsOfServiceAgreed;
+    }
+
+    @ManagedAttribute("The ACME account key file")
+    public String getAccountKeyPath()
+    {
+        return accountKeyPath;
+    }
+
+    public void setAccountKeyPath(String accountKeyPath)
+    {
+        this.accountKeyPath = accountKeyPath;
+    }
+
+    @ManagedAttribute("The key store file of the certificate")
+    public String getKeyStorePath()
+    {
+        return keyStorePath;
+    }
+
+    public void setKeyStorePath(String keyStorePath)
+    {
+        this.keyStorePath = keyStorePath;
+    }
+
+    public void setKeyStorePassword(String keyStorePassword)
+    {
+        this.keyStorePassword = keyStorePassword;
+    }
+
+    @ManagedAttribute("The number of days before the certificate expiration at which the certificate is renewed")
+    public int getRenewBeforeDays()
+    {
+        return renewBeforeDays;
+    }
+
+    public void setRenewBeforeDays(int renewBeforeDays)
+    {
+        this.renewBeforeDays = renewBeforeDays;
+    }
+
+    @ManagedAttribute("The interval in ms between certificate checks")
+    public long getCheckInterval()
+    {
+        return checkInterval;
+    }
+
+    public void setCheckInterval(long checkInterval)
+    {
+        this.checkInterval = checkInterval;
+    }
+
+    @ManagedAttribute("The interval in ms before a failed certificate renewal is retried")
+    public long getRetryInterval()
+    {
+        return retryInterval;
+    }
+
+    public void setRetryInterval(long retryInterval)
+    {
+        this.retryInterval = retryInterval;
+    }
+
+    @ManagedAttribute("The expiration of the ACME certificate, or null if not issued yet")
+    public String getCertificateExpiration()
+    {
+        Instant expiration = notAfter;
+        return expiration == null ? null : expiration.toString();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (domains.isEmpty())
+            throw new IllegalStateException("No domains");
+        if (!termsOfServiceAgreed)
+            throw new IllegalStateException("ACME terms of service not agreed");
+
+        if (httpClient == null)
+            setHttpClient(new HttpClient());
+        if (scheduler == null)
+        {
+            scheduler = new ScheduledExecutorScheduler("acme-" + domains.get(0), true);
+            addBean(scheduler);
+        }
+
+        Path path = Path.of(keyStorePath);
+        if (!Files.exists(path))
+        {
+            // Use a temporary certificate until one is issued.
+            KeyPair keyPair = AcmeCertificates.newKeyPair();
+            X509Certificate certificate = AcmeCertificates.newSelfSignedCertificate(keyPair, domains, Duration.ofDays(7));
+            AcmeCertificates.writeKeyStore(path, keyStorePassword, ALIAS, keyPair.getPrivate(), List.of(certificate));
+        }
+        sslContextFactory.setKeyStorePath(path.toAbsolutePath().toString());
+        sslContextFactory.setKeyStoreType("PKCS12");
+        sslContextFactory.setKeyStorePassword(keyStorePassword);
+        if (sslContextFactory.isRunning())
+            sslContextFactory.reload(scf -> {});
+
+        super.doStart();
+
+        schedule(0);
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (task != null)
+                task.cancel();
+            task = null;
+        }
+        super.doStop();
+    }
+
+    private void schedule(long delay)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (isStopping() || isStopped())
+                return;
+            task = scheduler.schedule(this::check, delay, TimeUnit.MILLISECONDS);
+        }
+    }
+
+    private void check()
+    {
+        long delay = checkInterval;
+        try
+        {
+            X509Certificate certificate = AcmeCertificates.readCertificate(Path.of(keyStorePath), keyStorePassword, ALIAS);
+            if (needsRenewal(certificate))
+                renew();
+            else
+                notAfter = certificate.getNotAfter().toInstant();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to renew ACME certificate for {}", domains, x);
+            delay = retryInterval;
+        }
+        schedule(delay);
+    }
+
+    private boolean needsRenewal(X509Certificate certificate)
+    {
+        if (certificate == null)
+            return true;
+        // A self-signed certificate is the temporary one.
+        if (certificate.getSubjectX500Principal().equals(certificate.getIssuerX500Principal()))
+            return true;
+        Instant renewal = certificate.getNotAfter().toInstant().minus(Duration.ofDays(renewBeforeDays));
+        return !Instant.now().isBefore(renewal);
+    }
+
+    /**
+     * <p>Obtains a new certificate from the ACME server, stores it in the key store
+     * and reloads the {@code SslContextFactory}.</p>
+     *
+     * @throws Exception if the certificate cannot be obtained
+     */
+    @ManagedOperation(value = "Renews the certificate", impact = "ACTION")
+    public void renew() throws Exception
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Renewing ACME certificate for {}", domains);
+
+            AcmeClient client = new AcmeClient(httpClient, URI.create(directoryURI), getAccountKeyPair());
+            client.newAccount(email, termsOfServiceAgreed);
+            KeyPair keyPair = AcmeCertificates.newKeyPair();
+            List<X509Certificate> chain = client.orderCertificate(domains, keyPair, challengeResponder);
+            AcmeCertificates.writeKeyStore(Path.of(keyStorePath), keyStorePassword, ALIAS, keyPair.getPrivate(), chain);
+            if (sslContextFactory.isRunning())
+                sslContextFactory.reload(scf -> {});
+            notAfter = chain.get(0).getNotAfter().toInstant();
+            LOG.info("Renewed ACME certificate for {} expiring {}", domains, notAfter);
+        }
+    }
+
+    private KeyPair getAccountKeyPair() throws Exception
+    {
+        Path path = Path.of(accountKeyPath);
+        if (Files.exists(path))
+            return AcmeCertificates.readKeyPair(path);
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        AcmeCertificates.writeKeyPair(keyPair, path);
+        return keyPair;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), domains, directoryURI);
+    }
+}
@@ -0,0 +1,314 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.io.ByteArrayInputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.math.BigInteger;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.security.GeneralSecurityException;
+import java.security.KeyFactory;
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.KeyStore;
+import java.security.PrivateKey;
+import java.security.PublicKey;
+import java.security.SecureRandom;
+import java.security.Signature;
+import java.security.cert.Certificate;
+import java.security.cert.CertificateFactory;
+import java.security.cert.X509Certificate;
+import java.security.spec.ECGenParameterSpec;
+import java.security.spec.PKCS8EncodedKeySpec;
+import java.security.spec.X509EncodedKeySpec;
+import java.time.Duration;
+import java.time.Instant;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.Collection;
+import java.util.List;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+
+/**
+ * <p>Utility methods to generate and store keys and certificates.</p>
+ * <p>Keys are always EC keys on the P-256 curve, signed with ECDSA and SHA-256.</p>
+ */
+public final class AcmeCertificates
+{
+    /**
+     * The OID of the {@code id-pe-acmeIdentifier} certificate extension of RFC 8737.
+     */
+    public static final String ACME_IDENTIFIER_OID = "1.3.6.1.5.5.7.1.31";
+    private static final String SUBJECT_ALT_NAME_OID = "2.5.29.17";
+    private static final String COMMON_NAME_OID = "2.5.4.3";
+    private static final String EXTENSION_REQUEST_OID = "1.2.840.113549.1.9.14";
+    private static final String ECDSA_WITH_SHA256_OID = "1.2.840.10045.4.3.2";
+    private static final Pattern PEM = Pattern.compile("-----BEGIN ([A-Z ]+)-----([^-]+)-----END \\1-----");
+    private static final SecureRandom RANDOM = new SecureRandom();
+
+    private AcmeCertificates()
+    {
+    }
+
+    /**
+     * @return a new EC key pair on the P-256 curve
+     * @throws GeneralSecurityException if the key pair cannot be generated
+     */
+    public static KeyPair newKeyPair() throws GeneralSecurityException
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("EC");
+        generator.initialize(new ECGenParameterSpec("secp256r1"), RANDOM);
+        return generator.generateKeyPair();
+    }
+
+    /**
+     * <p>Reads a key pair stored by {@link #writeKeyPair(KeyPair, Path)}.</p>
+     *
+     * @param path the file to read
+     * @return the key pair
+     * @throws IOException if the file cannot be read
+     * @throws GeneralSecurityException if the file does not contain a valid EC key pair
+     */
+    public static KeyPair readKeyPair(Path path) throws IOException, GeneralSecurityException
+    {
+        String pem = Files.readString(path, StandardCharsets.US_ASCII);
+        PrivateKey privateKey = null;
+        PublicKey publicKey = null;
+        KeyFactory keyFactory = KeyFactory.getInstance("EC");
+        Matcher matcher = PEM.matcher(pem);
+        while (matcher.find())
+        {
+            byte[] der = Base64.getMimeDecoder().decode(matcher.group(2));
+            if ("PRIVATE KEY".equals(matcher.group(1)))
+                privateKey = keyFactory.generatePrivate(new PKCS8EncodedKeySpec(der));
+            else if ("PUBLIC KEY".equals(matcher.group(1)))
+                publicKey = keyFactory.generatePublic(new X509EncodedKeySpec(der));
+        }
+        if (privateKey == null || publicKey == null)
+            throw new GeneralSecurityException("No key pair in " + path);
+        return new KeyPair(publicKey, privateKey);
+    }
+
+    /**
+     * <p>Writes the given key pair as a PEM file readable only by its owner, if supported.</p>
+     *
+     * @param keyPair the key pair to store
+     * @param path the file to write
+     * @throws IOException if the file cannot be written
+     */
+    public static void writeKeyPair(KeyPair keyPair, Path path) throws IOException
+    {
+        String pem = toPEM("PRIVATE KEY", keyPair.getPrivate().getEncoded()) +
+            toPEM("PUBLIC KEY", keyPair.getPublic().getEncoded());
+        Path parent = path.toAbsolutePath().getParent();
+        Files.createDirectories(parent);
+        Path temp = Files.createTempFile(parent, path.getFileName().toString(), ".tmp");
+        try
+        {
+            Files.writeString(temp, pem, StandardCharsets.US_ASCII);
+            Files.move(temp, path, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+        }
+        finally
+        {
+            Files.deleteIfExists(temp);
+        }
+    }
+
+    /**
+     * <p>Parses a PEM certificate chain, as downloaded from an ACME server.</p>
+     *
+     * @param pem the PEM encoded certificates
+     * @return the certificate chain, the end-entity certificate first
+     * @throws GeneralSecurityException if the certificates cannot be parsed
+     */
+    public static List<X509Certificate> parseCertificateChain(String pem) throws GeneralSecurityException
+    {
+        CertificateFactory factory = CertificateFactory.getInstance("X.509");
+        Collection<? extends Certificate> certificates = factory.generateCertificates(new ByteArrayInputStream(pem.getBytes(StandardCharsets.US_ASCII)));
+        List<X509Certificate> chain = new ArrayList<>();
+        for (Certificate certificate : certificates)
+        {
+            chain.add((X509Certificate)certificate);
+        }
+        if (chain.isEmpty())
+            throw new GeneralSecurityException("No certificates");
+        return chain;
+    }
+
+    /**
+     * <p>Creates a PKCS#10 certificate signing request for the given domains.</p>
+     *
+     * @param keyPair the key pair of the certificate
+     * @param domains the domains of the certificate, the first being the subject common name
+     * @return the DER encoded certificate signing request
+     * @throws GeneralSecurityException if the request cannot be signed
+     */
+    public static byte[] newCertificateSigningRequest(KeyPair keyPair, List<String> domains) throws GeneralSecurityException
+    {
+        byte[] extensionRequest = Der.sequence(
+            Der.oid(EXTENSION_REQUEST_OID),
+            Der.set(Der.sequence(subjectAltName(domains))));
+        byte[] info = Der.sequence(
+            Der.integer(BigInteger.ZERO),
+            name(domains.get(0)),
+            keyPair.getPublic().getEncoded(),
+            Der.implicitConstructed(0, extensionRequest));
+        return signed(info, keyPair.getPrivate());
+    }
+
+    /**
+     * <p>Creates a self-signed certificate for the given domains.</p>
+     *
+     * @param keyPair the key pair of the certificate
+     * @param domains the domains of the certificate, the first being the subject common name
+     * @param validity the validity of the certificate
+     * @return a new self-signed certificate
+     * @throws GeneralSecurityException if the certificate cannot be created
+     */
+    public static X509Certificate newSelfSignedCertificate(KeyPair keyPair, List<String> domains, Duration validity) throws GeneralSecurityException
+    {
+        return newSelfSignedCertificate(keyPair, domains, validity, subjectAltName(domains));
+    }
+
+    /**
+     * <p>Creates the self-signed certificate used to respond to a TLS-ALPN-01 challenge.</p>
+     *
+     * @param keyPair the key pair of the certificate
+     * @param domain the domain being validated
+     * @param keyAuthorization the key authorization of the challenge
+     * @return the self-signed challenge certificate
+     * @throws GeneralSecurityException if the certificate cannot be created
+     * @see <a href="https://datatracker.ietf.org/doc/html/rfc8737">RFC 8737</a>
+     */
+    public static X509Certificate newTlsAlpnChallengeCertificate(KeyPair keyPair, String domain, String keyAuthorization) throws GeneralSecurityException
+    {
+        byte[] digest = AcmeClient.sha256(keyAuthorization.getBytes(StandardCharsets.US_ASCII));
+        byte[] acmeIdentifier = Der.sequence(
+            Der.oid(ACME_IDENTIFIER_OID),
+            Der.bool(true),
+            Der.octetString(Der.octetString(digest)));
+        return newSelfSignedCertificate(keyPair, List.of(domain), Duration.ofDays(1), subjectAltName(List.of(domain)), acmeIdentifier);
+    }
+
+    private static X509Certificate newSelfSignedCertificate(KeyPair keyPair, List<String> domains, Duration validity, byte[]... extensions) throws GeneralSecurityException
+    {
+        Instant now = Instant.now();
+        byte[] name = name(domains.get(0));
+        byte[] tbs = Der.sequence(
+            Der.explicit(0, Der.integer(BigInteger.valueOf(2))),
+            Der.integer(new BigInteger(64, RANDOM).add(BigInteger.ONE)),
+            Der.sequence(Der.oid(ECDSA_WITH_SHA256_OID)),
+            name,
+            Der.sequence(Der.time(now.minus(Duration.ofMinutes(5))), Der.time(now.plus(validity))),
+            name,
+            keyPair.getPublic().getEncoded(),
+            Der.explicit(3, Der.sequence(extensions)));
+        byte[] der = signed(tbs, keyPair.getPrivate());
+        CertificateFactory factory = CertificateFactory.getInstance("X.509");
+        return (X509Certificate)factory.generateCertificate(new ByteArrayInputStream(der));
+    }
+
+    /**
+     * <p>Stores a private key and its certificate chain in a new PKCS12 key store file.</p>
+     * <p>The file is replaced atomically, so that it can be reloaded at any time.</p>
+     *
+     * @param path the key store file
+     * @param password the key store password, also used for the private key
+     * @param alias the alias of the private key entry
+     * @param privateKey the private key
+     * @param chain the certificate chain
+     * @throws IOException if the key store cannot be written
+     * @throws GeneralSecurityException if the key store cannot be created
+     */
+    public static void writeKeyStore(Path path, String password, String alias, PrivateKey privateKey, List<X509Certificate> chain) throws IOException, GeneralSecurityException
+    {
+        char[] chars = password == null ? new char[0] : password.toCharArray();
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        keyStore.load(null, null);
+        keyStore.setKeyEntry(alias, privateKey, chars, chain.toArray(new Certificate[0]));
+        Path parent = path.toAbsolutePath().getParent();
+        Files.createDirectories(parent);
+        Path temp = Files.createTempFile(parent, path.getFileName().toString(), ".tmp");
+        try
+        {
+            try (OutputStream output = Files.newOutputStream(temp))
+            {
+                keyStore.store(output, chars);
+            }
+            Files.move(temp, path, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+        }
+        finally
+        {
+            Files.deleteIfExists(temp);
+        }
+    }
+
+    /**
+     * @param path the key store file
+     * @param password the key store password
+     * @param alias the alias of the private key entry
+     * @return the certificate stored with the given alias, or null if the file
+     * does not exist or there is no such certificate
+     * @throws IOException if the key store cannot be read
+     * @throws GeneralSecurityException if the key store cannot be loaded
+     */
+    public static X509Certificate readCertificate(Path path, String password, String alias) throws IOException, GeneralSecurityException
+    {
+        if (!Files.exists(path))
+            return null;
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        try (InputStream input = Files.newInputStream(path))
+        {
+            keyStore.load(input, password == null ? new char[0] : password.toCharArray());
+        }
+        Certificate certificate = keyStore.getCertificate(alias);
+        return certificate instanceof X509Certificate ? (X509Certificate)certificate : null;
+    }
+
+    private static byte[] subjectAltName(List<String> domains)
+    {
+        byte[][] names = new byte[domains.size()][];
+        for (int i = 0; i < names.length; ++i)
+        {
+            // dNSName [2] IA5String
+            names[i] = Der.implicit(2, domains.get(i).getBytes(StandardCharsets.US_ASCII));
+        }
+        return Der.sequence(Der.oid(SUBJECT_ALT_NAME_OID), Der.octetString(Der.sequence(names)));
+    }
+
+    private static byte[] name(String commonName)
+    {
+        return Der.sequence(Der.set(Der.sequence(Der.oid(COMMON_NAME_OID), Der.utf8String(commonName))));
+    }
+
+    private static byte[] signed(byte[] content, PrivateKey privateKey) throws GeneralSecurityException
+    {
+        Signature signature = Signature.getInstance("SHA256withECDSA");
+        signature.initSign(privateKey);
+        signature.update(content);
+        return Der.sequence(content, Der.sequence(Der.oid(ECDSA_WITH_SHA256_OID)), Der.bitString(signature.sign()));
+    }
+
+    private static String toPEM(String type, byte[] der)
+    {
+        String base64 = Base64.getMimeEncoder(64, new byte[]{'\n'}).encodeToString(der);
+        return "-----BEGIN " + type + "-----\n" + base64 + "\n-----END " + type + "-----\n";
+    }
+}
@@ -0,0 +1,98 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that responds to ACME {@code http-01} challenges.</p>
+ * <p>Requests for {@code /.well-known/acme-challenge/<token>} are answered with the
+ * key authorization of the pending challenge; all other requests are forwarded
+ * to the wrapped handler.</p>
+ * <p>The ACME server always validates {@code http-01} challenges on port 80,
+ * so this handler must be reachable from a clear-text connector on that port.</p>
+ */
+@ManagedObject("ACME http-01 challenge handler")
+public class AcmeChallengeHandler extends HandlerWrapper implements AcmeChallengeResponder
+{
+    public static final String PATH_PREFIX = "/.well-known/acme-challenge/";
+    private static final Logger LOG = LoggerFactory.getLogger(AcmeChallengeHandler.class);
+
+    private final Map<String, String> challenges = new ConcurrentHashMap<>();
+
+    @Override
+    public String getType()
+    {
+        return "http-01";
+    }
+
+    @Override
+    public void addChallenge(String domain, String token, String keyAuthorization)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Adding challenge {} for {}", token, domain);
+        challenges.put(token, keyAuthorization);
+    }
+
+    @Override
+    public void removeChallenge(String domain, String token)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Removing challenge {} for {}", token, domain);
+        challenges.remove(token);
+    }
+
+    @ManagedAttribute("The number of pending challenges")
+    public int getChallenges()
+    {
+        return challenges.size();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        String path = baseRequest.getPathInContext();
+        if (path != null && path.startsWith(PATH_PREFIX) && HttpMethod.GET.is(request.getMethod()))
+        {
+            String keyAuthorization = challenges.get(path.substring(PATH_PREFIX.length()));
+            if (keyAuthorization != null)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Responding to challenge {}", path);
+                baseRequest.setHandled(true);
+                byte[] bytes = keyAuthorization.getBytes(StandardCharsets.US_ASCII);
+                response.setStatus(HttpServletResponse.SC_OK);
+                response.setContentType("application/octet-stream");
+                response.setContentLength(bytes.length);
+                response.getOutputStream().write(bytes);
+                return;
+            }
+        }
+        super.handle(target, baseRequest, request, response);
+    }
+}
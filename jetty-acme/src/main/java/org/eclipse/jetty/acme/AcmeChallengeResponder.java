@@ -0,0 +1,45 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+/**
+ * <p>Responds to ACME challenges of a given type, proving to the ACME server
+ * the control of a domain.</p>
+ * <p>Challenges are added just before the ACME server is asked to validate
+ * them, and removed when the validation completed, either successfully or not.</p>
+ *
+ * @see AcmeChallengeHandler
+ * @see AcmeSslContextFactory
+ */
+public interface AcmeChallengeResponder
+{
+    /**
+     * @return the challenge type, for example {@code http-01} or {@code tls-alpn-01}
+     */
+    String getType();
+
+    /**
+     * @param domain the domain being validated
+     * @param token the challenge token
+     * @param keyAuthorization the key authorization for the token
+     * @throws Exception if the challenge response cannot be provisioned
+     */
+    void addChallenge(String domain, String token, String keyAuthorization) throws Exception;
+
+    /**
+     * @param domain the domain that was validated
+     * @param token the challenge token
+     */
+    void removeChallenge(String domain, String token);
+}
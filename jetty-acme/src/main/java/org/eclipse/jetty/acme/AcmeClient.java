@@ -0,0 +1,389 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.io.IOException;
+import java.math.BigInteger;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.KeyPair;
+import java.security.MessageDigest;
+import java.security.Signature;
+import java.security.cert.X509Certificate;
+import java.security.interfaces.ECPublicKey;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Base64;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.util.StringRequestContent;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A minimal ACME client, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc8555">RFC 8555</a>.</p>
+ * <p>The client supports the account creation and the ordering of certificates
+ * for DNS identifiers, delegating the challenge responses to an
+ * {@link AcmeChallengeResponder}.
+ * Requests are signed with the given EC P-256 account key.</p>
+ * <p>Instances of this class are not thread-safe.</p>
+ */
+public class AcmeClient
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AcmeClient.class);
+    private static final String JOSE_JSON = "application/jose+json";
+    private static final String PROBLEM_JSON = "application/problem+json";
+    private static final String PEM_CERTIFICATE_CHAIN = "application/pem-certificate-chain";
+    private static final Base64.Encoder BASE64URL = Base64.getUrlEncoder().withoutPadding();
+
+    private final HttpClient httpClient;
+    private final URI directoryURI;
+    private final KeyPair accountKeyPair;
+    private Map<String, Object> directory;
+    private String nonce;
+    private String accountURI;
+    private Duration timeout = Duration.ofMinutes(2);
+
+    public AcmeClient(HttpClient httpClient, URI directoryURI, KeyPair accountKeyPair)
+    {
+        this.httpClient = Objects.requireNonNull(httpClient);
+        this.directoryURI = Objects.requireNonNull(directoryURI);
+        this.accountKeyPair = Objects.requireNonNull(accountKeyPair);
+    }
+
+    /**
+     * @return the max time to wait for validations and certificate issuance
+     */
+    public Duration getTimeout()
+    {
+        return timeout;
+    }
+
+    public void setTimeout(Duration timeout)
+    {
+        this.timeout = Objects.requireNonNull(timeout);
+    }
+
+    /**
+     * @return the account URI, or null if the account has not been created or found yet
+     */
+    public String getAccountURI()
+    {
+        return accountURI;
+    }
+
+    /**
+     * @return the URI of the terms of service of the ACME server, or null if there are none
+     * @throws Exception if the directory cannot be retrieved
+     */
+    public String getTermsOfService() throws Exception
+    {
+        Object meta = directory().get("meta");
+        return meta instanceof Map ? (String)((Map<?, ?>)meta).get("termsOfService") : null;
+    }
+
+    /**
+     * <p>Creates the account for the account key, or finds the existing one.</p>
+     *
+     * @param email the optional contact email
+     * @param termsOfServiceAgreed whether the terms of service of the ACME server have been agreed
+     * @return the account URI
+     * @throws Exception if the account cannot be created
+     */
+    public String newAccount(String email, boolean termsOfServiceAgreed) throws Exception
+    {
+        Map<String, Object> payload = new LinkedHashMap<>();
+        payload.put("termsOfServiceAgreed", termsOfServiceAgreed);
+        if (email != null)
+            payload.put("contact", new Object[]{"mailto:" + email});
+        ContentResponse response = post(resource("newAccount"), toJSON(payload));
+        accountURI = response.getHeaders().get(HttpHeader.LOCATION);
+        if (accountURI == null)
+            throw new IOException("Missing account location");
+        if (LOG.isDebugEnabled())
+            LOG.debug("Account {}", accountURI);
+        return accountURI;
+    }
+
+    /**
+     * <p>Orders a certificate for the given domains, validating each of them with the given responder.</p>
+     *
+     * @param domains the certificate domains
+     * @param certificateKeyPair the key pair of the certificate
+     * @param responder the challenge responder
+     * @return the issued certificate chain, the end-entity certificate first
+     * @throws Exception if the certificate cannot be issued
+     */
+    public List<X509Certificate> orderCertificate(List<String> domains, KeyPair certificateKeyPair, AcmeChallengeResponder responder) throws Exception
+    {
+        if (accountURI == null)
+            throw new IllegalStateException("No account");
+
+        List<Object> identifiers = new ArrayList<>();
+        for (String domain : domains)
+        {
+            identifiers.add(Map.of("type", "dns", "value", domain));
+        }
+        ContentResponse response = post(resource("newOrder"), toJSON(Map.of("identifiers", identifiers)));
+        String orderURI = response.getHeaders().get(HttpHeader.LOCATION);
+        Map<String, Object> order = parse(response);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Order {} {}", orderURI, order);
+
+        for (Object authorization : toList(order.get("authorizations")))
+        {
+            authorize((String)authorization, responder);
+        }
+
+        byte[] csr = AcmeCertificates.newCertificateSigningRequest(certificateKeyPair, domains);
+        order = parse(post((String)order.get("finalize"), toJSON(Map.of("csr", BASE64URL.encodeToString(csr)))));
+        order = poll(orderURI, order, "ready", "processing");
+        if (!"valid".equals(order.get("status")))
+            throw new IOException("Order failed " + order);
+
+        response = post((String)order.get("certificate"), "", PEM_CERTIFICATE_CHAIN);
+        return AcmeCertificates.parseCertificateChain(response.getContentAsString());
+    }
+
+    private void authorize(String authorizationURI, AcmeChallengeResponder responder) throws Exception
+    {
+        Map<String, Object> authorization = parse(post(authorizationURI, ""));
+        if ("valid".equals(authorization.get("status")))
+            return;
+
+        String domain = (String)((Map<?, ?>)authorization.get("identifier")).get("value");
+        Map<?, ?> challenge = null;
+        for (Object c : toList(authorization.get("challenges")))
+        {
+            if (responder.getType().equals(((Map<?, ?>)c).get("type")))
+                challenge = (Map<?, ?>)c;
+        }
+        if (challenge == null)
+            throw new IOException("No " + responder.getType() + " challenge for " + domain);
+
+        String token = (String)challenge.get("token");
+        responder.addChallenge(domain, token, getKeyAuthorization(token));
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Validating {} with {}", domain, challenge);
+            post((String)challenge.get("url"), "{}");
+            authorization = poll(authorizationURI, authorization, "pending");
+            if (!"valid".equals(authorization.get("status")))
+                throw new IOException("Authorization failed for " + domain + ": " + authorization);
+        }
+        finally
+        {
+            responder.removeChallenge(domain, token);
+        }
+    }
+
+    private Map<String, Object> poll(String uri, Map<String, Object> resource, String... statuses) throws Exception
+    {
+        long begin = NanoTime.now();
+        while (Arrays.asList(statuses).contains(resource.get("status")))
+        {
+            if (NanoTime.millisSince(begin) > timeout.toMillis())
+                throw new IOException("Timeout waiting for " + uri);
+            Thread.sleep(1000);
+            resource = parse(post(uri, ""));
+        }
+        return resource;
+    }
+
+    /**
+     * @param token the challenge token
+     * @return the key authorization for the given token
+     * @throws GeneralSecurityException if the account key thumbprint cannot be computed
+     */
+    public String getKeyAuthorization(String token) throws GeneralSecurityException
+    {
+        String jwk = toJSON(jwk());
+        return token + "." + BASE64URL.encodeToString(sha256(jwk.getBytes(StandardCharsets.UTF_8)));
+    }
+
+    private Map<String, Object> directory() throws Exception
+    {
+        if (directory == null)
+        {
+            ContentResponse response = httpClient.newRequest(directoryURI)
+                .timeout(timeout.toMillis(), TimeUnit.MILLISECONDS)
+                .send();
+            directory = parse(response);
+        }
+        return directory;
+    }
+
+    private String resource(String name) throws Exception
+    {
+        String uri = (String)directory().get(name);
+        if (uri == null)
+            throw new IOException("Missing directory resource " + name);
+        return uri;
+    }
+
+    private String nonce() throws Exception
+    {
+        String result = nonce;
+        nonce = null;
+        if (result == null)
+        {
+            ContentResponse response = httpClient.newRequest(resource("newNonce"))
+                .method(HttpMethod.HEAD)
+                .timeout(timeout.toMillis(), TimeUnit.MILLISECONDS)
+                .send();
+            result = response.getHeaders().get("Replay-Nonce");
+            if (result == null)
+                throw new IOException("Missing nonce");
+        }
+        return result;
+    }
+
+    private ContentResponse post(String uri, String payload) throws Exception
+    {
+        return post(uri, payload, null);
+    }
+
+    private ContentResponse post(String uri, String payload, String accept) throws Exception
+    {
+        for (int attempt = 0; ; ++attempt)
+        {
+            Request request = httpClient.newRequest(uri)
+                .method(HttpMethod.POST)
+                .timeout(timeout.toMillis(), TimeUnit.MILLISECONDS)
+                .body(new StringRequestContent(JOSE_JSON, sign(uri, payload)));
+            if (accept != null)
+                request.headers(headers -> headers.put(HttpHeader.ACCEPT, accept));
+            ContentResponse response = request.send();
+            nonce = response.getHeaders().get("Replay-Nonce");
+            try
+            {
+                if (PROBLEM_JSON.equals(response.getMediaType()))
+                {
+                    Map<String, Object> problem = parse(response);
+                    throw new AcmeException((String)problem.get("type"), (String)problem.get("detail"), response.getStatus());
+                }
+                if (response.getStatus() / 100 != 2)
+                    throw new IOException("Unexpected response " + response.getStatus() + " for " + uri);
+                return response;
+            }
+            catch (AcmeException x)
+            {
+                // Retry once with the fresh nonce returned with the error.
+                if (attempt > 0 || !AcmeException.BAD_NONCE.equals(x.getType()))
+                    throw x;
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Retrying {}", uri, x);
+            }
+        }
+    }
+
+    private String sign(String uri, String payload) throws Exception
+    {
+        Map<String, Object> header = new LinkedHashMap<>();
+        header.put("alg", "ES256");
+        header.put("nonce", nonce());
+        header.put("url", uri);
+        if (accountURI == null)
+            header.put("jwk", jwk());
+        else
+            header.put("kid", accountURI);
+
+        String protected64 = BASE64URL.encodeToString(toJSON(header).getBytes(StandardCharsets.UTF_8));
+        String payload64 = BASE64URL.encodeToString(payload.getBytes(StandardCharsets.UTF_8));
+
+        Signature signature = Signature.getInstance("SHA256withECDSAinP1363Format");
+        signature.initSign(accountKeyPair.getPrivate());
+        signature.update((protected64 + "." + payload64).getBytes(StandardCharsets.US_ASCII));
+
+        Map<String, Object> jws = new LinkedHashMap<>();
+        jws.put("protected", protected64);
+        jws.put("payload", payload64);
+        jws.put("signature", BASE64URL.encodeToString(signature.sign()));
+        return toJSON(jws);
+    }
+
+    private Map<String, Object> jwk()
+    {
+        // The members must be in lexicographic order, as required by RFC 7638.
+        ECPublicKey publicKey = (ECPublicKey)accountKeyPair.getPublic();
+        Map<String, Object> jwk = new LinkedHashMap<>();
+        jwk.put("crv", "P-256");
+        jwk.put("kty", "EC");
+        jwk.put("x", BASE64URL.encodeToString(toUnsigned(publicKey.getW().getAffineX())));
+        jwk.put("y", BASE64URL.encodeToString(toUnsigned(publicKey.getW().getAffineY())));
+        return jwk;
+    }
+
+    private static byte[] toUnsigned(BigInteger value)
+    {
+        byte[] bytes = value.toByteArray();
+        if (bytes.length == 32)
+            return bytes;
+        byte[] result = new byte[32];
+        if (bytes.length > 32)
+            System.arraycopy(bytes, bytes.length - 32, result, 0, 32);
+        else
+            System.arraycopy(bytes, 0, result, 32 - bytes.length, bytes.length);
+        return result;
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> parse(ContentResponse response) throws IOException
+    {
+        Object json = new JSON().fromJSON(response.getContentAsString());
+        if (!(json instanceof Map))
+            throw new IOException("Invalid JSON response " + response.getStatus());
+        return (Map<String, Object>)json;
+    }
+
+    private static List<?> toList(Object value)
+    {
+        if (value instanceof Object[])
+            return Arrays.asList((Object[])value);
+        if (value instanceof List)
+            return (List<?>)value;
+        return List.of();
+    }
+
+    private static String toJSON(Object value)
+    {
+        return new JSON().toJSON(value);
+    }
+
+    static byte[] sha256(byte[] bytes) throws GeneralSecurityException
+    {
+        return MessageDigest.getInstance("SHA-256").digest(bytes);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), directoryURI);
+    }
+}
@@ -0,0 +1,54 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.io.IOException;
+
+/**
+ * <p>An exception reporting an ACME problem document, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc8555#section-6.7">RFC 8555</a>.</p>
+ */
+public class AcmeException extends IOException
+{
+    /**
+     * The problem type returned when the request nonce was rejected by the server.
+     */
+    public static final String BAD_NONCE = "urn:ietf:params:acme:error:badNonce";
+
+    private final String type;
+    private final int status;
+
+    public AcmeException(String type, String detail, int status)
+    {
+        super(String.format("%s: %s (%d)", type, detail, status));
+        this.type = type;
+        this.status = status;
+    }
+
+    /**
+     * @return the problem type URN
+     */
+    public String getType()
+    {
+        return type;
+    }
+
+    /**
+     * @return the HTTP status code of the response carrying the problem
+     */
+    public int getStatus()
+    {
+        return status;
+    }
+}
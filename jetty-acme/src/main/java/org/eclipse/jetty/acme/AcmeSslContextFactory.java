@@ -0,0 +1,175 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.security.KeyPair;
+import java.security.KeyStore;
+import java.security.Principal;
+import java.security.PrivateKey;
+import java.security.cert.X509Certificate;
+import java.util.Locale;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import javax.net.ssl.ExtendedSSLSession;
+import javax.net.ssl.KeyManager;
+import javax.net.ssl.SNIHostName;
+import javax.net.ssl.SNIServerName;
+import javax.net.ssl.SSLEngine;
+import javax.net.ssl.SSLSession;
+import javax.net.ssl.StandardConstants;
+import javax.net.ssl.X509ExtendedKeyManager;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link SslContextFactory.Server} that responds to ACME {@code tls-alpn-01} challenges.</p>
+ * <p>While a challenge is pending for a domain, TLS handshakes for that domain that
+ * negotiate the {@value #ACME_TLS_1} application protocol are presented with the
+ * challenge certificate, as defined by <a href="https://datatracker.ietf.org/doc/html/rfc8737">RFC 8737</a>;
+ * all other handshakes are presented with the configured certificates.</p>
+ * <p>The {@value #ACME_TLS_1} protocol must be negotiable via ALPN, so the connector must
+ * be configured with an {@link AcmeTlsAlpnConnectionFactory}; furthermore, the selected
+ * application protocol is only known when the certificate is chosen with TLS 1.3,
+ * so TLS 1.3 must be enabled.</p>
+ */
+@ManagedObject("ACME tls-alpn-01 SslContextFactory")
+public class AcmeSslContextFactory extends SslContextFactory.Server implements AcmeChallengeResponder
+{
+    public static final String ACME_TLS_1 = "acme-tls/1";
+    private static final Logger LOG = LoggerFactory.getLogger(AcmeSslContextFactory.class);
+    private static final String ALIAS_PREFIX = "acme-tls-alpn-01:";
+
+    private final Map<String, Challenge> challenges = new ConcurrentHashMap<>();
+
+    @Override
+    public String getType()
+    {
+        return "tls-alpn-01";
+    }
+
+    @Override
+    public void addChallenge(String domain, String token, String keyAuthorization) throws Exception
+    {
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        X509Certificate certificate = AcmeCertificates.newTlsAlpnChallengeCertificate(keyPair, domain, keyAuthorization);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Adding challenge {} for {}", token, domain);
+        challenges.put(domain.toLowerCase(Locale.ENGLISH), new Challenge(token, keyPair.getPrivate(), certificate));
+    }
+
+    @Override
+    public void removeChallenge(String domain, String token)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Removing challenge {} for {}", token, domain);
+        challenges.computeIfPresent(domain.toLowerCase(Locale.ENGLISH), (d, c) -> c.token.equals(token) ? null : c);
+    }
+
+    @ManagedAttribute("The number of pending challenges")
+    public int getChallenges()
+    {
+        return challenges.size();
+    }
+
+    @Override
+    protected KeyManager[] getKeyManagers(KeyStore keyStore) throws Exception
+    {
+        KeyManager[] managers = super.getKeyManagers(keyStore);
+        if (managers == null)
+            return new KeyManager[]{new AcmeKeyManager(null)};
+        for (int i = 0; i < managers.length; ++i)
+        {
+            if (managers[i] instanceof X509ExtendedKeyManager)
+                managers[i] = new AcmeKeyManager((X509ExtendedKeyManager)managers[i]);
+        }
+        return managers;
+    }
+
+    private String chooseChallengeAlias(String keyType, SSLEngine engine)
+    {
+        if (challenges.isEmpty() || !"EC".equals(keyType) || !ACME_TLS_1.equals(engine.getHandshakeApplicationProtocol()))
+            return null;
+        SSLSession session = engine.getHandshakeSession();
+        if (!(session instanceof ExtendedSSLSession))
+            return null;
+        for (SNIServerName name : ((ExtendedSSLSession)session).getRequestedServerNames())
+        {
+            if (name.getType() == StandardConstants.SNI_HOST_NAME)
+            {
+                String domain = ((SNIHostName)name).getAsciiName().toLowerCase(Locale.ENGLISH);
+                if (challenges.containsKey(domain))
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Presenting challenge certificate for {}", domain);
+                    return ALIAS_PREFIX + domain;
+                }
+            }
+        }
+        return null;
+    }
+
+    private Challenge getChallenge(String alias)
+    {
+        if (alias == null || !alias.startsWith(ALIAS_PREFIX))
+            return null;
+        return challenges.get(alias.substring(ALIAS_PREFIX.length()));
+    }
+
+    private static class Challenge
+    {
+        private final String token;
+        private final PrivateKey privateKey;
+        private final X509Certificate certificate;
+
+        private Challenge(String token, PrivateKey privateKey, X509Certificate certificate)
+        {
+            this.token = token;
+            this.privateKey = privateKey;
+            this.certificate = certificate;
+        }
+    }
+
+    private class AcmeKeyManager extends X509ExtendedKeyManagerWrapper
+    {
+        private AcmeKeyManager(X509ExtendedKeyManager keyManager)
+        {
+            super(keyManager);
+        }
+
+        @Override
+        public String chooseEngineServerAlias(String keyType, Principal[] issuers, SSLEngine engine)
+        {
+            String alias = chooseChallengeAlias(keyType, engine);
+            return alias != null ? alias : super.chooseEngineServerAlias(keyType, issuers, engine);
+        }
+
+        @Override
+        public X509Certificate[] getCertificateChain(String alias)
+        {
+            Challenge challenge = getChallenge(alias);
+            return challenge != null ? new X509Certificate[]{challenge.certificate} : super.getCertificateChain(alias);
+        }
+
+        @Override
+        public PrivateKey getPrivateKey(String alias)
+        {
+            Challenge challenge = getChallenge(alias);
+            return challenge != null ? challenge.privateKey : super.getPrivateKey(alias);
+        }
+    }
+}
@@ -0,0 +1,67 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import org.eclipse.jetty.io.AbstractConnection;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.server.AbstractConnectionFactory;
+import org.eclipse.jetty.server.Connector;
+
+/**
+ * <p>The {@link org.eclipse.jetty.server.ConnectionFactory} for the {@value AcmeSslContextFactory#ACME_TLS_1}
+ * protocol, that must be negotiable via ALPN to respond to ACME {@code tls-alpn-01} challenges.</p>
+ * <p>The ACME server only validates the certificate presented during the TLS handshake,
+ * so the connections created by this factory are closed as soon as they are opened.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * AcmeSslContextFactory sslContextFactory = new AcmeSslContextFactory();
+ * ALPNServerConnectionFactory alpn = new ALPNServerConnectionFactory(h2.getProtocol(), http.getProtocol(), AcmeSslContextFactory.ACME_TLS_1);
+ * SslConnectionFactory ssl = new SslConnectionFactory(sslContextFactory, alpn.getProtocol());
+ * ServerConnector connector = new ServerConnector(server, ssl, alpn, h2, http, new AcmeTlsAlpnConnectionFactory());
+ * }</pre>
+ */
+public class AcmeTlsAlpnConnectionFactory extends AbstractConnectionFactory
+{
+    public AcmeTlsAlpnConnectionFactory()
+    {
+        super(AcmeSslContextFactory.ACME_TLS_1);
+    }
+
+    @Override
+    public Connection newConnection(Connector connector, EndPoint endPoint)
+    {
+        return configure(new AcmeTlsAlpnConnection(endPoint, connector), connector, endPoint);
+    }
+
+    private static class AcmeTlsAlpnConnection extends AbstractConnection
+    {
+        private AcmeTlsAlpnConnection(EndPoint endPoint, Connector connector)
+        {
+            super(endPoint, connector.getExecutor());
+        }
+
+        @Override
+        public void onOpen()
+        {
+            super.onOpen();
+            getEndPoint().close();
+        }
+
+        @Override
+        public void onFillable()
+        {
+        }
+    }
+}
@@ -0,0 +1,177 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.io.ByteArrayOutputStream;
+import java.math.BigInteger;
+import java.nio.charset.StandardCharsets;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.time.format.DateTimeFormatter;
+
+/**
+ * <p>A minimal ASN.1 DER encoder, sufficient to build certificate
+ * signing requests and self-signed certificates.</p>
+ */
+final class Der
+{
+    private static final int BOOLEAN = 0x01;
+    private static final int INTEGER = 0x02;
+    private static final int BIT_STRING = 0x03;
+    private static final int OCTET_STRING = 0x04;
+    private static final int OBJECT_IDENTIFIER = 0x06;
+    private static final int UTF8_STRING = 0x0C;
+    private static final int UTC_TIME = 0x17;
+    private static final int GENERALIZED_TIME = 0x18;
+    private static final int SEQUENCE = 0x30;
+    private static final int SET = 0x31;
+    private static final DateTimeFormatter UTC_TIME_FORMAT = DateTimeFormatter.ofPattern("yyMMddHHmmss'Z'").withZone(ZoneOffset.UTC);
+    private static final DateTimeFormatter GENERALIZED_TIME_FORMAT = DateTimeFormatter.ofPattern("yyyyMMddHHmmss'Z'").withZone(ZoneOffset.UTC);
+    // 2050-01-01T00:00:00Z, from when GeneralizedTime must be used (RFC 5280, 4.1.2.5).
+    private static final Instant UTC_TIME_LIMIT = Instant.ofEpochSecond(2524608000L);
+
+    private Der()
+    {
+    }
+
+    static byte[] sequence(byte[]... elements)
+    {
+        return tlv(SEQUENCE, concat(elements));
+    }
+
+    static byte[] set(byte[]... elements)
+    {
+        return tlv(SET, concat(elements));
+    }
+
+    static byte[] integer(BigInteger value)
+    {
+        return tlv(INTEGER, value.toByteArray());
+    }
+
+    static byte[] bool(boolean value)
+    {
+        return tlv(BOOLEAN, new byte[]{(byte)(value ? 0xFF : 0x00)});
+    }
+
+    static byte[] octetString(byte[] value)
+    {
+        return tlv(OCTET_STRING, value);
+    }
+
+    static byte[] bitString(byte[] value)
+    {
+        // No unused bits in the last byte.
+        byte[] content = new byte[value.length + 1];
+        System.arraycopy(value, 0, content, 1, value.length);
+        return tlv(BIT_STRING, content);
+    }
+
+    static byte[] utf8String(String value)
+    {
+        return tlv(UTF8_STRING, value.getBytes(StandardCharsets.UTF_8));
+    }
+
+    static byte[] time(Instant instant)
+    {
+        if (instant.isBefore(UTC_TIME_LIMIT))
+            return tlv(UTC_TIME, UTC_TIME_FORMAT.format(instant).getBytes(StandardCharsets.US_ASCII));
+        return tlv(GENERALIZED_TIME, GENERALIZED_TIME_FORMAT.format(instant).getBytes(StandardCharsets.US_ASCII));
+    }
+
+    static byte[] oid(String oid)
+    {
+        String[] parts = oid.split("\\.");
+        ByteArrayOutputStream out = new ByteArrayOutputStream();
+        out.write(Integer.parseInt(parts[0]) * 40 + Integer.parseInt(parts[1]));
+        for (int i = 2; i < parts.length; ++i)
+        {
+            long value = Long.parseLong(parts[i]);
+            // Base 128, most significant group first, with the high bit set on all but the last byte.
+            int groups = 1;
+            for (long v = value >>> 7; v != 0; v >>>= 7)
+            {
+                ++groups;
+            }
+            for (int g = groups - 1; g >= 0; --g)
+            {
+                int b = (int)((value >>> (7 * g)) & 0x7F);
+                out.write(g == 0 ? b : b | 0x80);
+            }
+        }
+        return tlv(OBJECT_IDENTIFIER, out.toByteArray());
+    }
+
+    /**
+     * @param tag the context specific tag number
+     * @param content the encoded element to wrap
+     * @return a context specific, constructed, explicitly tagged element
+     */
+    static byte[] explicit(int tag, byte[] content)
+    {
+        return tlv(0xA0 | tag, content);
+    }
+
+    /**
+     * @param tag the context specific tag number
+     * @param content the content octets of a primitive element
+     * @return a context specific, primitive, implicitly tagged element
+     */
+    static byte[] implicit(int tag, byte[] content)
+    {
+        return tlv(0x80 | tag, content);
+    }
+
+    /**
+     * @param tag the context specific tag number
+     * @param elements the encoded elements of a constructed element
+     * @return a context specific, constructed, implicitly tagged element
+     */
+    static byte[] implicitConstructed(int tag, byte[]... elements)
+    {
+        return tlv(0xA0 | tag, concat(elements));
+    }
+
+    static byte[] tlv(int tag, byte[] content)
+    {
+        ByteArrayOutputStream out = new ByteArrayOutputStream(content.length + 6);
+        out.write(tag);
+        int length = content.length;
+        if (length < 0x80)
+        {
+            out.write(length);
+        }
+        else
+        {
+            int bytes = (Integer.SIZE - Integer.numberOfLeadingZeros(length) + 7) / 8;
+            out.write(0x80 | bytes);
+            for (int i = bytes - 1; i >= 0; --i)
+            {
+                out.write(length >>> (8 * i));
+            }
+        }
+        out.write(content, 0, content.length);
+        return out.toByteArray();
+    }
+
+    private static byte[] concat(byte[]... elements)
+    {
+        ByteArrayOutputStream out = new ByteArrayOutputStream();
+        for (byte[] element : elements)
+        {
+            out.write(element, 0, element.length);
+        }
+        return out.toByteArray();
+    }
+}
@@ -0,0 +1,111 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Path;
+import java.security.KeyPair;
+import java.security.cert.X509Certificate;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.List;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.hasItem;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+@ExtendWith(WorkDirExtension.class)
+public class AcmeCertificatesTest
+{
+    public WorkDir workDir;
+
+    @Test
+    public void testSelfSignedCertificate() throws Exception
+    {
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        X509Certificate certificate = AcmeCertificates.newSelfSignedCertificate(keyPair, List.of("example.com", "www.example.com"), Duration.ofDays(1));
+
+        certificate.verify(keyPair.getPublic());
+        certificate.checkValidity();
+        assertThat(certificate.getSubjectX500Principal().getName(), is("CN=example.com"));
+        assertThat(dnsNames(certificate), contains("example.com", "www.example.com"));
+    }
+
+    @Test
+    public void testTlsAlpnChallengeCertificate() throws Exception
+    {
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        String keyAuthorization = "token.thumbprint";
+        X509Certificate certificate = AcmeCertificates.newTlsAlpnChallengeCertificate(keyPair, "example.com", keyAuthorization);
+
+        certificate.verify(keyPair.getPublic());
+        assertThat(certificate.getCriticalExtensionOIDs(), hasItem(AcmeCertificates.ACME_IDENTIFIER_OID));
+        // The extension value is an OCTET STRING wrapping the DER encoded OCTET STRING of the digest.
+        byte[] value = certificate.getExtensionValue(AcmeCertificates.ACME_IDENTIFIER_OID);
+        byte[] digest = AcmeClient.sha256(keyAuthorization.getBytes(StandardCharsets.US_ASCII));
+        byte[] expected = Der.octetString(Der.octetString(digest));
+        assertArrayEquals(expected, value);
+    }
+
+    @Test
+    public void testKeyStoreRoundTrip() throws Exception
+    {
+        Path keyStore = workDir.getEmptyPathDir().resolve("keystore.p12");
+        assertThat(AcmeCertificates.readCertificate(keyStore, "secret", "acme"), nullValue());
+
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        X509Certificate certificate = AcmeCertificates.newSelfSignedCertificate(keyPair, List.of("example.com"), Duration.ofDays(1));
+        AcmeCertificates.writeKeyStore(keyStore, "secret", "acme", keyPair.getPrivate(), List.of(certificate));
+
+        X509Certificate stored = AcmeCertificates.readCertificate(keyStore, "secret", "acme");
+        assertThat(stored, notNullValue());
+        assertEquals(certificate, stored);
+    }
+
+    @Test
+    public void testKeyPairRoundTrip() throws Exception
+    {
+        Path path = workDir.getEmptyPathDir().resolve("account.pem");
+        KeyPair keyPair = AcmeCertificates.newKeyPair();
+        AcmeCertificates.writeKeyPair(keyPair, path);
+
+        KeyPair stored = AcmeCertificates.readKeyPair(path);
+        assertArrayEquals(keyPair.getPrivate().getEncoded(), stored.getPrivate().getEncoded());
+        assertArrayEquals(keyPair.getPublic().getEncoded(), stored.getPublic().getEncoded());
+    }
+
+    private static List<String> dnsNames(X509Certificate certificate) throws Exception
+    {
+        List<String> names = new ArrayList<>();
+        Collection<List<?>> alternativeNames = certificate.getSubjectAlternativeNames();
+        for (List<?> alternativeName : alternativeNames)
+        {
+            // Type 2 is dNSName.
+            if (Integer.valueOf(2).equals(alternativeName.get(0)))
+                names.add((String)alternativeName.get(1));
+        }
+        return names;
+    }
+}
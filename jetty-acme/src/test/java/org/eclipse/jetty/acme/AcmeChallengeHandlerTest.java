@@ -0,0 +1,92 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.acme;
+
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class AcmeChallengeHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private AcmeChallengeHandler challengeHandler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        challengeHandler = new AcmeChallengeHandler();
+        challengeHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.NO_CONTENT_204);
+            }
+        });
+        server.setHandler(challengeHandler);
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testChallengeResponse() throws Exception
+    {
+        challengeHandler.addChallenge("example.com", "token", "token.thumbprint");
+
+        HttpTester.Response response = get(AcmeChallengeHandler.PATH_PREFIX + "token");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("token.thumbprint"));
+
+        // Unknown tokens are forwarded.
+        assertThat(get(AcmeChallengeHandler.PATH_PREFIX + "other").getStatus(), is(HttpStatus.NO_CONTENT_204));
+
+        challengeHandler.removeChallenge("example.com", "token");
+        assertThat(challengeHandler.getChallenges(), is(0));
+        assertThat(get(AcmeChallengeHandler.PATH_PREFIX + "token").getStatus(), is(HttpStatus.NO_CONTENT_204));
+    }
+
+    @Test
+    public void testOtherRequestsForwarded() throws Exception
+    {
+        assertThat(get("/index.html").getStatus(), is(HttpStatus.NO_CONTENT_204));
+    }
+}
@@ -0,0 +1,16 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Adds the /logging admin endpoint, to query and change at runtime
+the level of the loggers of the jetty-slf4j-impl logging.
+
+[tags]
+server
+logging
+
+[depend]
+admin
+logging-jetty
+
+[xml]
+etc/jetty-admin-logging.xml
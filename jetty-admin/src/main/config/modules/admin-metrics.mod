@@ -0,0 +1,15 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Adds the /metrics admin endpoint, serving the metrics of the metrics module
+from the admin connector.
+
+[tags]
+server
+
+[depend]
+admin
+metrics
+
+[xml]
+etc/jetty-admin-metrics.xml
@@ -0,0 +1,46 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Adds a dedicated admin connector, by default bound to the loopback interface,
+serving authenticated management endpoints: server dump (/dump),
+thread dump (/threads), connectors pause and resume (/connectors),
+session statistics (/sessions) and graceful drain (/drain).
+The web applications are not reachable from the admin connector,
+and the admin endpoints are not reachable from the other connectors.
+Add users with the admin role to etc/admin-realm.properties.
+Requests that modify the server state must have the X-Requested-By header
+or a JSON content type, and no cross-origin Origin header.
+
+[tags]
+server
+security
+
+[depend]
+server
+security
+
+[xml]
+etc/jetty-admin.xml
+
+[files]
+basehome:modules/admin/admin-realm.properties|etc/admin-realm.properties
+
+[lib]
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/jetty-admin-${jetty.version}.jar
+
+[ini-template]
+## The host of the admin connector.
+# jetty.admin.host=127.0.0.1
+
+## The port of the admin connector.
+# jetty.admin.port=8090
+
+## The idle timeout, in milliseconds, of the admin connector.
+# jetty.admin.idleTimeout=30000
+
+## The name of the admin realm.
+# jetty.admin.realm.name=Admin
+
+## The properties file of the admin realm users.
+# jetty.admin.realm.config=etc/admin-realm.properties
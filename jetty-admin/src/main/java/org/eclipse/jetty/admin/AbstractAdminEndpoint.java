@@ -0,0 +1,120 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Map;
+import java.util.Objects;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>Convenience base class for {@link AdminEndpoint}s, that provides
+ * methods to check the request method and to send JSON or text responses.</p>
+ */
+public abstract class AbstractAdminEndpoint implements AdminEndpoint
+{
+    private final String path;
+
+    protected AbstractAdminEndpoint(String path)
+    {
+        this.path = Objects.requireNonNull(path);
+    }
+
+    @Override
+    public String getPath()
+    {
+        return path;
+    }
+
+    /**
+     * <p>Checks that the request method is one of the given methods,
+     * otherwise sends a {@code 405} response.</p>
+     * <p>{@code HEAD} is allowed if {@code GET} is allowed.</p>
+     *
+     * @param request the request
+     * @param response the response
+     * @param methods the allowed methods
+     * @return whether the request method is allowed
+     * @throws IOException if the response cannot be written
+     */
+    protected boolean checkMethod(HttpServletRequest request, HttpServletResponse response, HttpMethod... methods) throws IOException
+    {
+        String method = request.getMethod();
+        for (HttpMethod allowed : methods)
+        {
+            if (allowed.is(method) || (allowed == HttpMethod.GET && HttpMethod.HEAD.is(method)))
+                return true;
+        }
+        response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+        return false;
+    }
+
+    /**
+     * @param response the response
+     * @param status the response status
+     * @param json the JSON object to send
+     * @throws IOException if the response cannot be written
+     */
+    protected void sendJSON(HttpServletResponse response, int status, Object json) throws IOException
+    {
+        send(response, status, MimeTypes.Type.APPLICATION_JSON_UTF_8.asString(), new JSON().toJSON(json));
+    }
+
+    /**
+     * <p>Sends a JSON object with an {@code error} field describing the given reason.</p>
+     *
+     * @param response the response
+     * @param status the response status
+     * @param reason the error reason
+     * @throws IOException if the response cannot be written
+     */
+    protected void sendJSONError(HttpServletResponse response, int status, String reason) throws IOException
+    {
+        sendJSON(response, status, Map.of("error", reason));
+    }
+
+    /**
+     * @param response the response
+     * @param text the text to send with status {@code 200}
+     * @throws IOException if the response cannot be written
+     */
+    protected void sendText(HttpServletResponse response, String text) throws IOException
+    {
+        send(response, HttpStatus.OK_200, MimeTypes.Type.TEXT_PLAIN_UTF_8.asString(), text);
+    }
+
+    private void send(HttpServletResponse response, int status, String contentType, String content) throws IOException
+    {
+        byte[] bytes = content.getBytes(StandardCharsets.UTF_8);
+        response.setStatus(status);
+        response.setContentType(contentType);
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-store");
+        response.setContentLength(bytes.length);
+        response.getOutputStream().write(bytes);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[path=%s]", getClass().getSimpleName(), hashCode(), path);
+    }
+}
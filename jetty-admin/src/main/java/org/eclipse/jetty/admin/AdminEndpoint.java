@@ -0,0 +1,44 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+/**
+ * <p>An endpoint of the {@link AdminHandler}, that serves a management function
+ * at a path of the admin connector.</p>
+ * <p>Endpoints are only invoked for requests that have been authenticated
+ * and authorized by the {@link AdminHandler}.</p>
+ *
+ * @see AbstractAdminEndpoint
+ */
+public interface AdminEndpoint
+{
+    /**
+     * @return the path of this endpoint, such as {@code /dump}
+     */
+    String getPath();
+
+    /**
+     * <p>Handles a request to this endpoint.</p>
+     *
+     * @param admin the admin handler, that gives access to the server
+     * @param request the request
+     * @param response the response
+     * @throws IOException if the response cannot be written
+     */
+    void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException;
+}
@@ -0,0 +1,313 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentSkipListMap;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.security.ConstraintMapping;
+import org.eclipse.jetty.security.ConstraintSecurityHandler;
+import org.eclipse.jetty.security.LoginService;
+import org.eclipse.jetty.security.authentication.BasicAuthenticator;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.URIUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.Graceful;
+import org.eclipse.jetty.util.security.Constraint;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that serves management {@link AdminEndpoint}s
+ * on a dedicated admin connector, passing the requests of all other connectors
+ * to the wrapped handler.</p>
+ * <p>The admin connector is identified by its {@link Connector#getName() name},
+ * by default {@code admin}, and should be bound to a private interface.
+ * Requests received by the admin connector never reach the wrapped handler,
+ * so applications are not exposed on the admin connector.</p>
+ * <p>All the endpoints are protected by the {@link #getSecurityHandler() security handler},
+ * that by default requires BASIC authentication by a user with one of the
+ * {@link #setRoles(String...) roles}, by default {@code admin}.
+ * The {@link LoginService} is either {@link #setLoginService(LoginService) set}
+ * explicitly or found as a bean of the {@link Server}.</p>
+ * <p>{@code GET /} lists the paths of the endpoints; requests that modify the
+ * server state are logged along with the authenticated user.</p>
+ * <p>Requests that modify the server state, that is with a method other than
+ * {@code GET} or {@code HEAD}, are protected against cross-site request forgery:
+ * they must either have the {@value #REQUESTED_BY_HEADER} header (with any value)
+ * or a JSON {@code Content-Type}, that browsers cannot send cross-origin without
+ * a preflight request, and their {@code Origin} header, if present, must match
+ * the scheme, host and port of the request, otherwise they are rejected with
+ * a {@code 403} response. For example:</p>
+ * <pre>{@code
+ * curl -u admin -X POST -H "X-Requested-By: cli" "http://localhost:8081/drain"
+ * }</pre>
+ */
+@ManagedObject("Admin handler")
+public class AdminHandler extends HandlerWrapper
+{
+    /**
+     * The name of the header that marks requests that modify the server state
+     * as not being cross-site forged.
+     */
+    public static final String REQUESTED_BY_HEADER = "X-Requested-By";
+    private static final Logger LOG = LoggerFactory.getLogger(AdminHandler.class);
+
+    private final Map<String, AdminEndpoint> endpoints = new ConcurrentSkipListMap<>();
+    private final ContextHandler context = new ContextHandler();
+    private final ConstraintSecurityHandler securityHandler = new ConstraintSecurityHandler();
+    private final AdminEndpoint index = new IndexEndpoint();
+    private String connectorName = "admin";
+    private String[] roles = {"admin"};
+
+    public AdminHandler()
+    {
+        context.setContextPath("/");
+        context.setDisplayName("admin");
+        context.setHandler(securityHandler);
+        securityHandler.setAuthenticator(new BasicAuthenticator());
+        securityHandler.setHandler(new EndpointsHandler());
+        addBean(context);
+    }
+
+    /**
+     * @return the name of the connector that serves the endpoints
+     */
+    @ManagedAttribute("The name of the admin connector")
+    public String getConnectorName()
+    {
+        return connectorName;
+    }
+
+    /**
+     * @param connectorName the name of the connector that serves the endpoints
+     */
+    public void setConnectorName(String connectorName)
+    {
+        this.connectorName = Objects.requireNonNull(connectorName);
+    }
+
+    /**
+     * @return the roles allowed to access the endpoints
+     */
+    @ManagedAttribute("The roles allowed to access the endpoints")
+    public String[] getRoles()
+    {
+        return roles.clone();
+    }
+
+    /**
+     * @param roles the roles allowed to access the endpoints
+     */
+    public void setRoles(String... roles)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        this.roles = roles.clone();
+    }
+
+    /**
+     * @return the security handler that protects the endpoints
+     */
+    public ConstraintSecurityHandler getSecurityHandler()
+    {
+        return securityHandler;
+    }
+
+    /**
+     * <p>Convenience method equivalent to {@code getSecurityHandler().setLoginService(loginService)}.</p>
+     *
+     * @param loginService the login service that authenticates the users of the endpoints
+     */
+    public void setLoginService(LoginService loginService)
+    {
+        securityHandler.setLoginService(loginService);
+    }
+
+    /**
+     * @param endpoint the endpoint to add, replacing the endpoint with the same path, if any
+     */
+    public void addEndpoint(AdminEndpoint endpoint)
+    {
+        AdminEndpoint existing = endpoints.put(endpoint.getPath(), endpoint);
+        if (existing != null)
+            removeBean(existing);
+        addBean(endpoint);
+    }
+
+    /**
+     * @param endpoint the endpoint to remove
+     * @return whether the endpoint was removed
+     */
+    public boolean removeEndpoint(AdminEndpoint endpoint)
+    {
+        removeBean(endpoint);
+        return endpoints.remove(endpoint.getPath(), endpoint);
+    }
+
+    /**
+     * @return the endpoints, sorted by path
+     */
+    public List<AdminEndpoint> getEndpoints()
+    {
+        return new ArrayList<>(endpoints.values());
+    }
+
+    /**
+     * @param connector the connector to test
+     * @return whether the given connector is the admin connector
+     */
+    public boolean isAdminConnector(Connector connector)
+    {
+        return connector != null && connectorName.equals(connector.getName());
+    }
+
+    /**
+     * <p>Returns whether the given {@link Graceful} component serves the endpoints,
+     * so that it must not be shut down when draining the server.</p>
+     *
+     * @param graceful the component to test
+     * @return whether the given component belongs to the admin connector or to this handler
+     */
+    public boolean isAdminComponent(Graceful graceful)
+    {
+        if (graceful instanceof Connector && isAdminConnector((Connector)graceful))
+            return true;
+        return graceful == context || context.getContainedBeans(Graceful.class).contains(graceful);
+    }
+
+    @Override
+    public void setServer(Server server)
+    {
+        super.setServer(server);
+        context.setServer(server);
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        Constraint constraint = new Constraint();
+        constraint.setName("admin");
+        constraint.setRoles(roles);
+        constraint.setAuthenticate(true);
+        ConstraintMapping mapping = new ConstraintMapping();
+        mapping.setPathSpec("/*");
+        mapping.setConstraint(constraint);
+        securityHandler.setConstraintMappings(List.of(mapping));
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (!isAdminConnector(baseRequest.getHttpChannel().getConnector()))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        context.handle(target, baseRequest, request, response);
+
+        if (!baseRequest.isHandled())
+        {
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.NOT_FOUND_404);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[connector=%s,endpoints=%s]", getClass().getSimpleName(), hashCode(), connectorName, endpoints.keySet());
+    }
+
+    private class EndpointsHandler extends AbstractHandler
+    {
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            AdminEndpoint endpoint = "/".equals(target) ? index : endpoints.get(target);
+            if (endpoint == null)
+                return;
+
+            baseRequest.setHandled(true);
+            if (!HttpMethod.GET.is(request.getMethod()) && !HttpMethod.HEAD.is(request.getMethod()))
+            {
+                String reason = checkCrossSite(request);
+                if (reason != null)
+                {
+                    LOG.warn("Rejected admin {} {} by {} from {}: {}", request.getMethod(), request.getRequestURI(), request.getRemoteUser(), request.getRemoteAddr(), reason);
+                    response.sendError(HttpStatus.FORBIDDEN_403, reason);
+                    return;
+                }
+                LOG.info("Admin {} {} by {} from {}", request.getMethod(), request.getRequestURI(), request.getRemoteUser(), request.getRemoteAddr());
+            }
+            endpoint.handle(AdminHandler.this, request, response);
+        }
+
+        private String checkCrossSite(HttpServletRequest request)
+        {
+            String origin = request.getHeader(HttpHeader.ORIGIN.asString());
+            if (origin != null && !origin.equalsIgnoreCase(originOf(request)))
+                return "cross-origin request from " + origin;
+
+            if (StringUtil.isNotBlank(request.getHeader(REQUESTED_BY_HEADER)))
+                return null;
+            String contentType = request.getContentType();
+            if (contentType != null && MimeTypes.Type.APPLICATION_JSON.is(MimeTypes.getContentTypeWithoutCharset(contentType)))
+                return null;
+            return "missing " + REQUESTED_BY_HEADER + " header";
+        }
+
+        private String originOf(HttpServletRequest request)
+        {
+            StringBuilder builder = new StringBuilder();
+            URIUtil.appendSchemeHostPort(builder, request.getScheme(), request.getServerName(), request.getServerPort());
+            return builder.toString();
+        }
+    }
+
+    private class IndexEndpoint extends AbstractAdminEndpoint
+    {
+        private IndexEndpoint()
+        {
+            super("/");
+        }
+
+        @Override
+        public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            if (checkMethod(request, response, HttpMethod.GET))
+                sendJSON(response, HttpStatus.OK_200, Map.of("endpoints", endpoints.keySet().toArray()));
+        }
+    }
+}
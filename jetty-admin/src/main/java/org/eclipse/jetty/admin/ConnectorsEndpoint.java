@@ -0,0 +1,132 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.AbstractConnector;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.NetworkConnector;
+
+/**
+ * <p>An {@link AdminEndpoint} that lists, by default at {@code /connectors},
+ * the connectors of the server, and pauses or resumes the accepting of
+ * new connections by a connector.</p>
+ * <ul>
+ * <li>{@code GET} returns the connectors, with their name, protocols, state
+ * and whether they are accepting connections;</li>
+ * <li>{@code POST} pauses or resumes the connector given by the {@code connector}
+ * parameter, depending on the {@code action} parameter, either {@code pause}
+ * or {@code resume}.
+ * The existing connections of a paused connector are not affected.</li>
+ * </ul>
+ * <p>The admin connector cannot be paused.</p>
+ */
+public class ConnectorsEndpoint extends AbstractAdminEndpoint
+{
+    public ConnectorsEndpoint()
+    {
+        this("/connectors");
+    }
+
+    public ConnectorsEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET, HttpMethod.POST))
+            return;
+
+        if (HttpMethod.GET.is(request.getMethod()) || HttpMethod.HEAD.is(request.getMethod()))
+        {
+            List<Object> connectors = new ArrayList<>();
+            for (Connector connector : admin.getServer().getConnectors())
+            {
+                connectors.add(toJSON(admin, connector));
+            }
+            sendJSON(response, HttpStatus.OK_200, Map.of("connectors", connectors.toArray()));
+            return;
+        }
+
+        String name = request.getParameter("connector");
+        String action = request.getParameter("action");
+        boolean pause = "pause".equals(action);
+        if (!pause && !"resume".equals(action))
+        {
+            sendJSONError(response, HttpStatus.BAD_REQUEST_400, "invalid action " + action);
+            return;
+        }
+
+        Connector connector = findConnector(admin, name);
+        if (connector == null)
+        {
+            sendJSONError(response, HttpStatus.NOT_FOUND_404, "no connector " + name);
+            return;
+        }
+        if (admin.isAdminConnector(connector))
+        {
+            sendJSONError(response, HttpStatus.CONFLICT_409, "cannot " + action + " the admin connector");
+            return;
+        }
+        if (!(connector instanceof AbstractConnector))
+        {
+            sendJSONError(response, HttpStatus.NOT_IMPLEMENTED_501, "cannot " + action + " connector " + name);
+            return;
+        }
+
+        ((AbstractConnector)connector).setAccepting(!pause);
+        sendJSON(response, HttpStatus.OK_200, toJSON(admin, connector));
+    }
+
+    private Connector findConnector(AdminHandler admin, String name)
+    {
+        if (name == null)
+            return null;
+        for (Connector connector : admin.getServer().getConnectors())
+        {
+            if (name.equals(connector.getName()))
+                return connector;
+        }
+        return null;
+    }
+
+    private Map<String, Object> toJSON(AdminHandler admin, Connector connector)
+    {
+        Map<String, Object> json = new LinkedHashMap<>();
+        json.put("name", connector.getName());
+        json.put("protocols", connector.getProtocols().toArray());
+        json.put("state", connector.getState());
+        if (connector instanceof NetworkConnector)
+        {
+            NetworkConnector networkConnector = (NetworkConnector)connector;
+            json.put("host", networkConnector.getHost());
+            json.put("port", networkConnector.getLocalPort());
+        }
+        if (connector instanceof AbstractConnector)
+            json.put("accepting", ((AbstractConnector)connector).isAccepting());
+        json.put("admin", admin.isAdminConnector(connector));
+        return json;
+    }
+}
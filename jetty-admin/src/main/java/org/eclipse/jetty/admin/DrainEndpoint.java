@@ -0,0 +1,85 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.CompletableFuture;
+import java.util.stream.Collectors;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.component.Graceful;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link AdminEndpoint} that triggers, by default at {@code /drain},
+ * the graceful shutdown of the server components, without stopping the server.</p>
+ * <p>A {@code POST} request shuts down all the {@link Graceful} components of the
+ * server, except those of the admin connector, so that connectors stop accepting
+ * connections and contexts reject new requests while the existing ones complete;
+ * a {@code GET} request returns whether the drain is started and whether it is done.</p>
+ * <p>A drain cannot be cancelled: the server must be stopped once drained.</p>
+ */
+public class DrainEndpoint extends AbstractAdminEndpoint
+{
+    private static final Logger LOG = LoggerFactory.getLogger(DrainEndpoint.class);
+
+    private CompletableFuture<Void> drain;
+
+    public DrainEndpoint()
+    {
+        this("/drain");
+    }
+
+    public DrainEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET, HttpMethod.POST))
+            return;
+
+        boolean post = HttpMethod.POST.is(request.getMethod());
+        CompletableFuture<Void> future;
+        synchronized (this)
+        {
+            if (drain == null && post)
+                drain = drain(admin);
+            future = drain;
+        }
+
+        Map<String, Object> json = new LinkedHashMap<>();
+        json.put("draining", future != null);
+        json.put("drained", future != null && future.isDone());
+        sendJSON(response, post ? HttpStatus.ACCEPTED_202 : HttpStatus.OK_200, json);
+    }
+
+    private CompletableFuture<Void> drain(AdminHandler admin)
+    {
+        List<Graceful> gracefuls = admin.getServer().getContainedBeans(Graceful.class).stream()
+            .filter(graceful -> !admin.isAdminComponent(graceful))
+            .collect(Collectors.toList());
+        LOG.info("Draining {}", gracefuls);
+        return CompletableFuture.allOf(gracefuls.stream().map(Graceful::shutdown).toArray(CompletableFuture[]::new));
+    }
+}
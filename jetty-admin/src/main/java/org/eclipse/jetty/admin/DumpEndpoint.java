@@ -0,0 +1,44 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+
+/**
+ * <p>An {@link AdminEndpoint} that serves, by default at {@code /dump},
+ * the dump of the server component tree as text.</p>
+ */
+public class DumpEndpoint extends AbstractAdminEndpoint
+{
+    public DumpEndpoint()
+    {
+        this("/dump");
+    }
+
+    public DumpEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (checkMethod(request, response, HttpMethod.GET))
+            sendText(response, admin.getServer().dump());
+    }
+}
@@ -0,0 +1,95 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.logging.JettyLoggerFactory;
+import org.slf4j.ILoggerFactory;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link AdminEndpoint} that reads and changes, by default at {@code /logging},
+ * the levels of the loggers of the {@code jetty-slf4j-impl} logging implementation.</p>
+ * <ul>
+ * <li>{@code GET} returns the levels of all the loggers, or of the
+ * logger given by the {@code logger} parameter;</li>
+ * <li>{@code POST} sets the level of the logger given by the {@code logger}
+ * parameter to the level given by the {@code level} parameter.</li>
+ * </ul>
+ * <p>Requests fail with {@code 501} if the SLF4J binding is not {@code jetty-slf4j-impl}.</p>
+ */
+public class LoggingEndpoint extends AbstractAdminEndpoint
+{
+    public LoggingEndpoint()
+    {
+        this("/logging");
+    }
+
+    public LoggingEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET, HttpMethod.POST))
+            return;
+
+        ILoggerFactory factory = LoggerFactory.getILoggerFactory();
+        if (!(factory instanceof JettyLoggerFactory))
+        {
+            sendJSONError(response, HttpStatus.NOT_IMPLEMENTED_501, "unsupported logging implementation " + factory.getClass().getName());
+            return;
+        }
+        JettyLoggerFactory loggers = (JettyLoggerFactory)factory;
+
+        String logger = request.getParameter("logger");
+        if (HttpMethod.POST.is(request.getMethod()))
+        {
+            String level = request.getParameter("level");
+            if (logger == null || level == null)
+            {
+                sendJSONError(response, HttpStatus.BAD_REQUEST_400, "missing logger or level");
+                return;
+            }
+            if (!loggers.setLoggerLevel(logger, level))
+            {
+                sendJSONError(response, HttpStatus.BAD_REQUEST_400, "invalid level " + level);
+                return;
+            }
+        }
+
+        Map<String, Object> levels = new LinkedHashMap<>();
+        if (logger == null)
+        {
+            for (String name : loggers.getLoggerNames())
+            {
+                levels.put(name, loggers.getLoggerLevel(name));
+            }
+        }
+        else
+        {
+            levels.put(logger, loggers.getLoggerLevel(logger));
+        }
+        sendJSON(response, HttpStatus.OK_200, levels);
+    }
+}
@@ -0,0 +1,81 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Objects;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.metrics.MetricsHandler;
+import org.eclipse.jetty.metrics.MetricsRegistry;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>An {@link AdminEndpoint} that serves, by default at {@code /metrics},
+ * the metrics of a {@link MetricsRegistry}, in the same formats as the
+ * {@link MetricsHandler}, so that metrics can be scraped from the admin connector.</p>
+ */
+public class MetricsEndpoint extends AbstractAdminEndpoint
+{
+    private static final String OPENMETRICS_CONTENT_TYPE = "application/openmetrics-text; version=1.0.0; charset=utf-8";
+    private static final String PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8";
+
+    private final MetricsRegistry registry;
+
+    public MetricsEndpoint(@Name("registry") MetricsRegistry registry)
+    {
+        this("/metrics", registry);
+    }
+
+    public MetricsEndpoint(@Name("path") String path, @Name("registry") MetricsRegistry registry)
+    {
+        super(path);
+        this.registry = Objects.requireNonNull(registry);
+    }
+
+    /**
+     * @return the registry of the metrics served by this endpoint
+     */
+    public MetricsRegistry getMetricsRegistry()
+    {
+        return registry;
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET))
+            return;
+
+        if (registry.getServer() == null)
+            registry.setServer(admin.getServer());
+
+        String accept = request.getHeader(HttpHeader.ACCEPT.asString());
+        boolean openMetrics = accept != null && accept.contains("application/openmetrics-text");
+        StringBuilder builder = new StringBuilder();
+        registry.write(builder, openMetrics);
+        byte[] content = builder.toString().getBytes(StandardCharsets.UTF_8);
+
+        response.setStatus(HttpStatus.OK_200);
+        response.setContentType(openMetrics ? OPENMETRICS_CONTENT_TYPE : PROMETHEUS_CONTENT_TYPE);
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-store");
+        response.setContentLength(content.length);
+        response.getOutputStream().write(content);
+    }
+}
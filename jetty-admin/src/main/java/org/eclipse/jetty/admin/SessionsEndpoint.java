@@ -0,0 +1,77 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.session.DefaultSessionCache;
+import org.eclipse.jetty.server.session.SessionCache;
+import org.eclipse.jetty.server.session.SessionHandler;
+
+/**
+ * <p>An {@link AdminEndpoint} that serves, by default at {@code /sessions},
+ * the session statistics of each context of the server that has a
+ * {@link SessionHandler}, as JSON.</p>
+ */
+public class SessionsEndpoint extends AbstractAdminEndpoint
+{
+    public SessionsEndpoint()
+    {
+        this("/sessions");
+    }
+
+    public SessionsEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET))
+            return;
+
+        Map<String, Object> contexts = new LinkedHashMap<>();
+        for (Handler handler : admin.getServer().getChildHandlersByClass(ContextHandler.class))
+        {
+            ContextHandler context = (ContextHandler)handler;
+            SessionHandler sessionHandler = context.getChildHandlerByClass(SessionHandler.class);
+            if (sessionHandler == null)
+                continue;
+
+            Map<String, Object> json = new LinkedHashMap<>();
+            json.put("created", sessionHandler.getSessionsCreated());
+            json.put("timeMax", sessionHandler.getSessionTimeMax());
+            json.put("timeMean", sessionHandler.getSessionTimeMean());
+            json.put("timeTotal", sessionHandler.getSessionTimeTotal());
+            SessionCache cache = sessionHandler.getSessionCache();
+            if (cache instanceof DefaultSessionCache)
+            {
+                DefaultSessionCache defaultCache = (DefaultSessionCache)cache;
+                json.put("current", defaultCache.getSessionsCurrent());
+                json.put("max", defaultCache.getSessionsMax());
+            }
+            contexts.put(context.getContextPath(), json);
+        }
+        sendJSON(response, HttpStatus.OK_200, Map.of("contexts", contexts));
+    }
+}
@@ -0,0 +1,94 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.io.IOException;
+import java.lang.management.LockInfo;
+import java.lang.management.ManagementFactory;
+import java.lang.management.MonitorInfo;
+import java.lang.management.ThreadInfo;
+import java.lang.management.ThreadMXBean;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+
+/**
+ * <p>An {@link AdminEndpoint} that serves, by default at {@code /threads},
+ * a dump of all the JVM threads as text, with their stack traces and the
+ * monitors and synchronizers they hold.</p>
+ */
+public class ThreadDumpEndpoint extends AbstractAdminEndpoint
+{
+    public ThreadDumpEndpoint()
+    {
+        this("/threads");
+    }
+
+    public ThreadDumpEndpoint(String path)
+    {
+        super(path);
+    }
+
+    @Override
+    public void handle(AdminHandler admin, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (!checkMethod(request, response, HttpMethod.GET))
+            return;
+
+        ThreadMXBean threads = ManagementFactory.getThreadMXBean();
+        StringBuilder builder = new StringBuilder();
+        for (ThreadInfo info : threads.dumpAllThreads(threads.isObjectMonitorUsageSupported(), threads.isSynchronizerUsageSupported()))
+        {
+            dump(builder, info);
+        }
+        sendText(response, builder.toString());
+    }
+
+    private static void dump(StringBuilder builder, ThreadInfo info)
+    {
+        builder.append('"').append(info.getThreadName()).append("\" #").append(info.getThreadId());
+        if (info.isDaemon())
+            builder.append(" daemon");
+        builder.append(' ').append(info.getThreadState());
+        if (info.getLockName() != null)
+            builder.append(" on ").append(info.getLockName());
+        if (info.getLockOwnerName() != null)
+            builder.append(" owned by \"").append(info.getLockOwnerName()).append("\" #").append(info.getLockOwnerId());
+        builder.append('\n');
+
+        StackTraceElement[] stackTrace = info.getStackTrace();
+        MonitorInfo[] monitors = info.getLockedMonitors();
+        for (int depth = 0; depth < stackTrace.length; ++depth)
+        {
+            builder.append("\tat ").append(stackTrace[depth]).append('\n');
+            for (MonitorInfo monitor : monitors)
+            {
+                if (monitor.getLockedStackDepth() == depth)
+                    builder.append("\t- locked ").append(monitor).append('\n');
+            }
+        }
+
+        LockInfo[] synchronizers = info.getLockedSynchronizers();
+        if (synchronizers.length > 0)
+        {
+            builder.append("\tLocked synchronizers:\n");
+            for (LockInfo synchronizer : synchronizers)
+            {
+                builder.append("\t- ").append(synchronizer).append('\n');
+            }
+        }
+        builder.append('\n');
+    }
+}
@@ -0,0 +1,226 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.admin;
+
+import java.nio.charset.StandardCharsets;
+import java.util.Base64;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.security.HashLoginService;
+import org.eclipse.jetty.security.UserStore;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.security.Credential;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+
+public class AdminHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private LocalConnector adminConnector;
+    private AdminHandler adminHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        connector.setName("http");
+        server.addConnector(connector);
+        adminConnector = new LocalConnector(server);
+        adminConnector.setName("admin");
+        server.addConnector(adminConnector);
+
+        UserStore userStore = new UserStore();
+        userStore.addUser("admin", Credential.getCredential("secret"), new String[]{"admin"});
+        userStore.addUser("user", Credential.getCredential("secret"), new String[]{"user"});
+        HashLoginService loginService = new HashLoginService("Admin");
+        loginService.setUserStore(userStore);
+
+        adminHandler = new AdminHandler();
+        adminHandler.setLoginService(loginService);
+        adminHandler.addEndpoint(new DumpEndpoint());
+        adminHandler.addEndpoint(new ThreadDumpEndpoint());
+        adminHandler.addEndpoint(new ConnectorsEndpoint());
+        adminHandler.addEndpoint(new DrainEndpoint());
+        adminHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.ACCEPTED_202);
+            }
+        });
+        server.setHandler(adminHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response request(LocalConnector connector, String method, String uri, String user) throws Exception
+    {
+        if ("GET".equals(method))
+            return request(connector, method, uri, user, Map.of());
+        return request(connector, method, uri, user, Map.of(AdminHandler.REQUESTED_BY_HEADER, "test"));
+    }
+
+    private HttpTester.Response request(LocalConnector connector, String method, String uri, String user, Map<String, String> headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder();
+        request.append(method).append(" ").append(uri).append(" HTTP/1.1\r\n");
+        request.append("Host: localhost\r\n");
+        if (user != null)
+        {
+            String credentials = Base64.getEncoder().encodeToString((user + ":secret").getBytes(StandardCharsets.ISO_8859_1));
+            request.append("Authorization: Basic ").append(credentials).append("\r\n");
+        }
+        headers.forEach((name, value) -> request.append(name).append(": ").append(value).append("\r\n"));
+        request.append("Content-Length: 0\r\n");
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(connector.getResponse(request.toString()));
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> parse(HttpTester.Response response)
+    {
+        return (Map<String, Object>)new JSON().fromJSON(response.getContent());
+    }
+
+    @Test
+    public void testAuthenticationRequired() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request(adminConnector, "GET", "/dump", null);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.get(HttpHeader.WWW_AUTHENTICATE), containsString("Admin"));
+
+        response = request(adminConnector, "GET", "/dump", "user");
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testAdminConnectorIsolation() throws Exception
+    {
+        server.start();
+
+        // The admin endpoints are not reachable from other connectors.
+        assertThat(request(connector, "GET", "/dump", "admin").getStatus(), is(HttpStatus.ACCEPTED_202));
+        // The application is not reachable from the admin connector.
+        assertThat(request(adminConnector, "GET", "/other", "admin").getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testDumps() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request(adminConnector, "GET", "/", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("/threads"));
+
+        response = request(adminConnector, "GET", "/dump", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("AdminHandler"));
+
+        response = request(adminConnector, "GET", "/threads", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString(Thread.currentThread().getName()));
+
+        response = request(adminConnector, "DELETE", "/dump", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.METHOD_NOT_ALLOWED_405));
+    }
+
+    @Test
+    public void testPauseResumeConnector() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request(adminConnector, "POST", "/connectors?connector=http&action=pause", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(parse(response).get("accepting"), is(false));
+        assertThat(connector.isAccepting(), is(false));
+
+        response = request(adminConnector, "POST", "/connectors?connector=admin&action=pause", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.CONFLICT_409));
+        response = request(adminConnector, "POST", "/connectors?connector=none&action=pause", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.NOT_FOUND_404));
+
+        response = request(adminConnector, "POST", "/connectors?connector=http&action=resume", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(connector.isAccepting(), is(true));
+    }
+
+    @Test
+    public void testCrossSiteRequestsRejected() throws Exception
+    {
+        server.start();
+
+        // A form POST that a browser may send cross-site.
+        HttpTester.Response response = request(adminConnector, "POST", "/connectors?connector=http&action=pause", "admin",
+            Map.of("Content-Type", "application/x-www-form-urlencoded"));
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        response = request(adminConnector, "POST", "/drain", "admin", Map.of());
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        response = request(adminConnector, "POST", "/drain", "admin",
+            Map.of(AdminHandler.REQUESTED_BY_HEADER, "test", "Origin", "http://evil.com"));
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(connector.isAccepting(), is(true));
+        assertThat(connector.isShutdown(), is(false));
+
+        response = request(adminConnector, "POST", "/connectors?connector=http&action=pause", "admin",
+            Map.of("Content-Type", "application/json", "Origin", "http://localhost"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(connector.isAccepting(), is(false));
+    }
+
+    @Test
+    public void testDrain() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request(adminConnector, "GET", "/drain", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(parse(response).get("draining"), is(false));
+
+        response = request(adminConnector, "POST", "/drain", "admin");
+        assertThat(response.getStatus(), is(HttpStatus.ACCEPTED_202));
+        assertThat(parse(response).get("draining"), is(true));
+
+        // The admin connector keeps working while draining.
+        await().atMost(5, TimeUnit.SECONDS).until(() -> (Boolean)parse(request(adminConnector, "GET", "/drain", "admin")).get("drained"), is(true));
+        assertThat(connector.isShutdown(), is(true));
+        assertThat(adminConnector.isShutdown(), is(false));
+    }
+}
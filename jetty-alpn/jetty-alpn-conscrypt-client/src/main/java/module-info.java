@@ -20,4 +20,6 @@ module org.eclipse.jetty.alpn.conscrypt.client
 
     provides org.eclipse.jetty.io.ssl.ALPNProcessor.Client with
         org.eclipse.jetty.alpn.conscrypt.client.ConscryptClientALPNProcessor;
+    provides org.eclipse.jetty.io.ssl.ECHProcessor.Client with
+        org.eclipse.jetty.alpn.conscrypt.client.ConscryptClientECHProcessor;
 }
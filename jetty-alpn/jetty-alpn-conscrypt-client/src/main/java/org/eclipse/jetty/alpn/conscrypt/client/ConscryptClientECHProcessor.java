@@ -0,0 +1,99 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.alpn.conscrypt.client;
+
+import java.lang.invoke.MethodHandle;
+import java.lang.invoke.MethodHandles;
+import java.lang.invoke.MethodType;
+import javax.net.ssl.SSLEngine;
+
+import org.conscrypt.Conscrypt;
+import org.eclipse.jetty.io.ssl.ECHProcessor;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Configures TLS Encrypted Client Hello on Conscrypt {@link SSLEngine}s.</p>
+ * <p>The ECH methods of {@link Conscrypt} are looked up at runtime, so that this
+ * processor is only available with Conscrypt versions that support ECH.</p>
+ */
+public class ConscryptClientECHProcessor implements ECHProcessor.Client
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ConscryptClientECHProcessor.class);
+
+    private MethodHandle setEchConfigList;
+    private MethodHandle setUseEchGrease;
+    private MethodHandle echAccepted;
+
+    @Override
+    public void init()
+    {
+        try
+        {
+            MethodHandles.Lookup lookup = MethodHandles.publicLookup();
+            setEchConfigList = lookup.findStatic(Conscrypt.class, "setEchConfigList", MethodType.methodType(void.class, SSLEngine.class, byte[].class));
+            setUseEchGrease = lookup.findStatic(Conscrypt.class, "setUseEchGrease", MethodType.methodType(void.class, SSLEngine.class, boolean.class));
+            echAccepted = lookup.findStatic(Conscrypt.class, "echAccepted", MethodType.methodType(boolean.class, SSLEngine.class));
+        }
+        catch (ReflectiveOperationException x)
+        {
+            throw new UnsupportedOperationException("Encrypted Client Hello not supported by this Conscrypt version", x);
+        }
+    }
+
+    @Override
+    public boolean appliesTo(SSLEngine sslEngine)
+    {
+        return sslEngine.getClass().getName().startsWith("org.conscrypt.");
+    }
+
+    @Override
+    public void configure(SSLEngine sslEngine, byte[] echConfigList, boolean grease)
+    {
+        try
+        {
+            if (echConfigList != null)
+                setEchConfigList.invoke(sslEngine, echConfigList);
+            else
+                setUseEchGrease.invoke(sslEngine, grease);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Configured ECH config={} grease={} for {}", echConfigList != null, grease, sslEngine);
+        }
+        catch (RuntimeException | Error x)
+        {
+            throw x;
+        }
+        catch (Throwable x)
+        {
+            throw new RuntimeException(x);
+        }
+    }
+
+    @Override
+    public boolean isAccepted(SSLEngine sslEngine)
+    {
+        try
+        {
+            return (boolean)echAccepted.invoke(sslEngine);
+        }
+        catch (RuntimeException | Error x)
+        {
+            throw x;
+        }
+        catch (Throwable x)
+        {
+            throw new RuntimeException(x);
+        }
+    }
+}
@@ -41,6 +41,7 @@ import javax.servlet.annotation.HandlesTypes;
 import org.eclipse.jetty.annotations.AnnotationParser.Handler;
 import org.eclipse.jetty.plus.webapp.PlusConfiguration;
 import org.eclipse.jetty.servlet.ServletContainerInitializerHolder;
+import org.eclipse.jetty.servlet.ServletContextHandler;
 import org.eclipse.jetty.servlet.Source;
 import org.eclipse.jetty.servlet.Source.Origin;
 import org.eclipse.jetty.util.JavaVersion;
@@ -460,6 +461,15 @@ public class AnnotationConfiguration extends AbstractConfiguration
             holder.resolveClasses(map);
             context.addServletContainerInitializer(holder); //only add the holder now all classes are fully available
         }
+
+        //SCIs with an explicit order must not be started concurrently
+        ServletContainerInitializerOrdering initializerOrdering = getInitializerOrdering(context);
+        if ((initializerOrdering != null && !initializerOrdering.isDefaultOrder()) || context.getMetaData().getOrdering() != null)
+        {
+            ServletContextHandler.ServletContainerInitializerStarter starter = context.getBean(ServletContextHandler.ServletContainerInitializerStarter.class);
+            if (starter != null)
+                starter.setOrdered(true);
+        }
     }
 
     @Override
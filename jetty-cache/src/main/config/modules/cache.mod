@@ -0,0 +1,34 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Caches responses in memory, according to the HTTP caching
+semantics of RFC 9111, using the response Cache-Control,
+Expires and Vary headers.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-cache.xml
+
+[lib]
+lib/jetty-cache-${jetty.version}.jar
+
+[ini-template]
+## Whether the cache is shared (for example a reverse proxy) or private.
+# jetty.cache.shared=true
+
+## The max size in bytes of a cached response content.
+# jetty.cache.maxEntrySize=1048576
+
+## The max total size in bytes of the cached responses.
+# jetty.cache.maxSize=67108864
+
+## The max number of cached responses.
+# jetty.cache.maxEntries=10000
+
+## Whether to store the cached responses in direct (off-heap) buffers.
+# jetty.cache.direct=false
@@ -0,0 +1,190 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.util.Collections;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.QuotedCSV;
+
+/**
+ * <p>The directives of the {@code Cache-Control} header, as defined by RFC 9111
+ * and by RFC 5861 for the {@code stale-while-revalidate} and {@code stale-if-error}
+ * extensions.</p>
+ * <p>Directives with a delta-seconds argument return {@code -1} when absent.</p>
+ */
+public class CacheControl
+{
+    public static final CacheControl EMPTY = new CacheControl(Collections.emptyMap());
+
+    private final Map<String, String> directives;
+
+    private CacheControl(Map<String, String> directives)
+    {
+        this.directives = directives;
+    }
+
+    /**
+     * <p>Parses the {@code Cache-Control} headers of the given fields.</p>
+     * <p>The HTTP/1.0 {@code Pragma: no-cache} header is treated as
+     * {@code Cache-Control: no-cache} when no {@code Cache-Control} header is present.</p>
+     *
+     * @param fields the request or response fields
+     * @return the parsed directives
+     */
+    public static CacheControl from(HttpFields fields)
+    {
+        List<String> values = fields.getValuesList(HttpHeader.CACHE_CONTROL);
+        if (values.isEmpty())
+        {
+            if (fields.contains(HttpHeader.PRAGMA, "no-cache"))
+                return new CacheControl(Collections.singletonMap("no-cache", null));
+            return EMPTY;
+        }
+
+        Map<String, String> directives = new HashMap<>();
+        QuotedCSV csv = new QuotedCSV(false);
+        values.forEach(csv::addValue);
+        for (String value : csv.getValues())
+        {
+            int equals = value.indexOf('=');
+            String name = (equals < 0 ? value : value.substring(0, equals)).trim().toLowerCase(Locale.ENGLISH);
+            String argument = equals < 0 ? null : value.substring(equals + 1).trim();
+            // Per RFC 9111, the first occurrence of a directive wins.
+            directives.putIfAbsent(name, argument);
+        }
+        return new CacheControl(directives);
+    }
+
+    /**
+     * @param name the directive name
+     * @return whether the directive is present
+     */
+    public boolean has(String name)
+    {
+        return directives.containsKey(name);
+    }
+
+    /**
+     * @param name the directive name
+     * @return the directive argument, or null if the directive is absent or has no argument
+     */
+    public String get(String name)
+    {
+        return directives.get(name);
+    }
+
+    /**
+     * @param name the directive name
+     * @return the delta-seconds argument of the directive, or -1 if the directive is absent or invalid
+     */
+    public long getSeconds(String name)
+    {
+        String value = directives.get(name);
+        if (value == null)
+            return -1;
+        try
+        {
+            long seconds = Long.parseLong(value);
+            return seconds < 0 ? -1 : seconds;
+        }
+        catch (NumberFormatException x)
+        {
+            // RFC 9111 section 1.2.2, a delta-seconds too large for the implementation is the max value.
+            return value.chars().allMatch(Character::isDigit) && !value.isEmpty() ? Long.MAX_VALUE : -1;
+        }
+    }
+
+    public boolean isNoStore()
+    {
+        return has("no-store");
+    }
+
+    public boolean isNoCache()
+    {
+        return has("no-cache");
+    }
+
+    public boolean isPrivate()
+    {
+        return has("private");
+    }
+
+    public boolean isPublic()
+    {
+        return has("public");
+    }
+
+    public boolean isMustRevalidate()
+    {
+        return has("must-revalidate");
+    }
+
+    public boolean isProxyRevalidate()
+    {
+        return has("proxy-revalidate");
+    }
+
+    public boolean isOnlyIfCached()
+    {
+        return has("only-if-cached");
+    }
+
+    public long getMaxAge()
+    {
+        return getSeconds("max-age");
+    }
+
+    public long getSMaxAge()
+    {
+        return getSeconds("s-maxage");
+    }
+
+    public long getMinFresh()
+    {
+        return getSeconds("min-fresh");
+    }
+
+    /**
+     * @return the {@code max-stale} argument, {@link Long#MAX_VALUE} if the directive
+     * has no argument, or -1 if the directive is absent
+     */
+    public long getMaxStale()
+    {
+        if (has("max-stale") && get("max-stale") == null)
+            return Long.MAX_VALUE;
+        return getSeconds("max-stale");
+    }
+
+    public long getStaleWhileRevalidate()
+    {
+        return getSeconds("stale-while-revalidate");
+    }
+
+    public long getStaleIfError()
+    {
+        return getSeconds("stale-if-error");
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), directives);
+    }
+}
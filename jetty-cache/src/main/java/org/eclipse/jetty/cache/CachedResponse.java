@@ -0,0 +1,226 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.nio.ByteBuffer;
+import java.util.EnumSet;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+
+/**
+ * <p>A response stored by {@link HttpCacheHandler} in a {@link HttpCacheStore}.</p>
+ * <p>Instances are immutable; the content is exposed as a read-only buffer.</p>
+ */
+public class CachedResponse
+{
+    private static final EnumSet<HttpHeader> NOT_UPDATED_HEADERS = EnumSet.of(
+        HttpHeader.CONTENT_LENGTH,
+        HttpHeader.CONTENT_ENCODING,
+        HttpHeader.CONTENT_TYPE,
+        HttpHeader.CONTENT_RANGE,
+        HttpHeader.TRANSFER_ENCODING
+    );
+
+    private final int status;
+    private final HttpFields headers;
+    private final ByteBuffer content;
+    private final long requestTime;
+    private final long responseTime;
+    private CacheControl cacheControl;
+
+    /**
+     * @param status the response status
+     * @param headers the response headers, without hop-by-hop headers
+     * @param content the response content
+     * @param requestTime the time in milliseconds since the epoch when the request was received
+     * @param responseTime the time in milliseconds since the epoch when the response was generated
+     */
+    public CachedResponse(int status, HttpFields headers, ByteBuffer content, long requestTime, long responseTime)
+    {
+        this.status = status;
+        this.headers = Objects.requireNonNull(headers).asImmutable();
+        this.content = Objects.requireNonNull(content).asReadOnlyBuffer();
+        this.requestTime = requestTime;
+        this.responseTime = responseTime;
+    }
+
+    public int getStatus()
+    {
+        return status;
+    }
+
+    public HttpFields getHeaders()
+    {
+        return headers;
+    }
+
+    /**
+     * @return a read-only view of the response content
+     */
+    public ByteBuffer getContent()
+    {
+        return content.slice();
+    }
+
+    public int getContentLength()
+    {
+        return content.remaining();
+    }
+
+    public long getRequestTime()
+    {
+        return requestTime;
+    }
+
+    public long getResponseTime()
+    {
+        return responseTime;
+    }
+
+    public String getETag()
+    {
+        return headers.get(HttpHeader.ETAG);
+    }
+
+    /**
+     * @return the {@code Last-Modified} time in milliseconds since the epoch, or -1
+     */
+    public long getLastModified()
+    {
+        return getDateField(HttpHeader.LAST_MODIFIED);
+    }
+
+    /**
+     * @return the {@code Date} time in milliseconds since the epoch, defaulting to the response time
+     */
+    public long getDate()
+    {
+        long date = getDateField(HttpHeader.DATE);
+        return date < 0 ? responseTime : date;
+    }
+
+    private long getDateField(HttpHeader header)
+    {
+        try
+        {
+            return headers.getDateField(header.asString());
+        }
+        catch (IllegalArgumentException x)
+        {
+            return -1;
+        }
+    }
+
+    public CacheControl getCacheControl()
+    {
+        // Benign race, the result is always the same.
+        CacheControl result = cacheControl;
+        if (result == null)
+            cacheControl = result = CacheControl.from(headers);
+        return result;
+    }
+
+    /**
+     * <p>Computes the current age of this response, as defined in RFC 9111 section 4.2.3.</p>
+     *
+     * @param now the current time in milliseconds since the epoch
+     * @return the current age in milliseconds
+     */
+    public long getCurrentAge(long now)
+    {
+        long ageValue = 0;
+        String age = headers.get(HttpHeader.AGE);
+        if (age != null)
+        {
+            try
+            {
+                ageValue = TimeUnit.SECONDS.toMillis(Long.parseLong(age.trim()));
+            }
+            catch (NumberFormatException ignored)
+            {
+            }
+        }
+        long apparentAge = Math.max(0, responseTime - getDate());
+        long responseDelay = responseTime - requestTime;
+        long correctedAgeValue = ageValue + responseDelay;
+        long correctedInitialAge = Math.max(apparentAge, correctedAgeValue);
+        long residentTime = now - responseTime;
+        return correctedInitialAge + residentTime;
+    }
+
+    /**
+     * <p>Computes the explicit freshness lifetime of this response,
+     * as defined in RFC 9111 section 4.2.1.</p>
+     *
+     * @param shared whether the computation is for a shared cache
+     * @return the freshness lifetime in milliseconds, or -1 if the response has no explicit freshness lifetime
+     */
+    public long getFreshnessLifetime(boolean shared)
+    {
+        CacheControl cacheControl = getCacheControl();
+        long seconds = shared ? cacheControl.getSMaxAge() : -1;
+        if (seconds < 0)
+            seconds = cacheControl.getMaxAge();
+        if (seconds >= 0)
+            return seconds > Long.MAX_VALUE / 1000 ? Long.MAX_VALUE : TimeUnit.SECONDS.toMillis(seconds);
+        String expires = headers.get(HttpHeader.EXPIRES);
+        if (expires != null)
+        {
+            // An invalid Expires, for example "0", means already expired.
+            long time = getDateField(HttpHeader.EXPIRES);
+            return time < 0 ? 0 : Math.max(0, time - getDate());
+        }
+        return -1;
+    }
+
+    /**
+     * <p>Returns a new response with the stored headers updated with those of
+     * a {@code 304 Not Modified} response, as defined in RFC 9111 section 3.2.</p>
+     *
+     * @param notModifiedHeaders the headers of the {@code 304} response
+     * @param requestTime the time in milliseconds since the epoch when the revalidation request was received
+     * @param responseTime the time in milliseconds since the epoch when the {@code 304} response was generated
+     * @return a new response with updated headers and times
+     */
+    public CachedResponse update(HttpFields notModifiedHeaders, long requestTime, long responseTime)
+    {
+        HttpFields.Mutable updated = HttpFields.build(headers);
+        for (HttpField field : notModifiedHeaders)
+        {
+            if (field.getHeader() != null && NOT_UPDATED_HEADERS.contains(field.getHeader()))
+                continue;
+            updated.remove(field.getName());
+        }
+        for (HttpField field : notModifiedHeaders)
+        {
+            if (field.getHeader() != null && NOT_UPDATED_HEADERS.contains(field.getHeader()))
+                continue;
+            updated.add(field);
+        }
+        // The Age of the 304 response replaces the stored one.
+        if (!notModifiedHeaders.contains(HttpHeader.AGE))
+            updated.remove(HttpHeader.AGE);
+        return new CachedResponse(status, updated, content, requestTime, responseTime);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%d,length=%d,etag=%s]", getClass().getSimpleName(), hashCode(), status, getContentLength(), getETag());
+    }
+}
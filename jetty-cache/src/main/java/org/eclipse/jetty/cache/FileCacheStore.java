@@ -0,0 +1,393 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.MappedByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.nio.file.StandardOpenOption;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import java.util.Comparator;
+import java.util.Iterator;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.stream.Collectors;
+import java.util.stream.Stream;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HttpCacheStore} that stores each response in a file of a directory,
+ * and serves the response content from memory mapped files.</p>
+ * <p>The stored responses survive restarts: when this store is started, the
+ * directory is scanned and the valid files are indexed, while invalid files
+ * are deleted.</p>
+ * <p>When the max size is exceeded, the least recently used responses are evicted.</p>
+ */
+@ManagedObject("File HTTP cache store")
+public class FileCacheStore extends AbstractLifeCycle implements HttpCacheStore
+{
+    private static final Logger LOG = LoggerFactory.getLogger(FileCacheStore.class);
+    private static final int MAGIC = 0x4A434631;
+    private static final String SUFFIX = ".cache";
+
+    private final AutoLock lock = new AutoLock();
+    private final Map<String, Entry> entries = new LinkedHashMap<>(16, 0.75F, true);
+    private final Path directory;
+    private final long maxSize;
+    private long size;
+
+    /**
+     * @param directory the directory where responses are stored
+     * @param maxSize the max size in bytes of the stored files
+     */
+    public FileCacheStore(@Name("directory") Path directory, @Name("maxSize") long maxSize)
+    {
+        this.directory = Objects.requireNonNull(directory);
+        this.maxSize = maxSize;
+    }
+
+    @ManagedAttribute("The directory where responses are stored")
+    public Path getDirectory()
+    {
+        return directory;
+    }
+
+    @ManagedAttribute("The max size in bytes of the stored files")
+    public long getMaxSize()
+    {
+        return maxSize;
+    }
+
+    @ManagedAttribute("The size in bytes of the stored files")
+    public long getSize()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return size;
+        }
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        Files.createDirectories(directory);
+        List<Path> paths;
+        try (Stream<Path> stream = Files.list(directory))
+        {
+            paths = stream.filter(path -> path.getFileName().toString().endsWith(SUFFIX))
+                .sorted(Comparator.comparing(this::lastModified))
+                .collect(Collectors.toList());
+        }
+        try (AutoLock l = lock.lock())
+        {
+            for (Path path : paths)
+            {
+                try
+                {
+                    Entry entry = new Entry(path, Files.size(path));
+                    String key = entry.load().key;
+                    if (path.equals(pathOf(key)))
+                    {
+                        entries.put(key, entry);
+                        size += entry.size;
+                        continue;
+                    }
+                }
+                catch (Throwable x)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Invalid cache file {}", path, x);
+                }
+                delete(path);
+            }
+            evict();
+        }
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        try (AutoLock l = lock.lock())
+        {
+            entries.clear();
+            size = 0;
+        }
+        super.doStop();
+    }
+
+    @Override
+    public CachedResponse get(String key)
+    {
+        Entry entry;
+        try (AutoLock l = lock.lock())
+        {
+            entry = entries.get(key);
+        }
+        if (entry == null)
+            return null;
+        try
+        {
+            Loaded loaded = entry.load();
+            if (key.equals(loaded.key))
+                return loaded.response;
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not load cache file {}", entry.path, x);
+        }
+        remove(key);
+        return null;
+    }
+
+    @Override
+    public boolean put(String key, CachedResponse response)
+    {
+        Path path = pathOf(key);
+        Path temp = null;
+        try
+        {
+            ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+            try (DataOutputStream metaData = new DataOutputStream(bytes))
+            {
+                metaData.writeUTF(key);
+                metaData.writeInt(response.getStatus());
+                metaData.writeLong(response.getRequestTime());
+                metaData.writeLong(response.getResponseTime());
+                metaData.writeInt(response.getHeaders().size());
+                for (HttpField field : response.getHeaders())
+                {
+                    metaData.writeUTF(field.getName());
+                    metaData.writeUTF(field.getValue());
+                }
+            }
+            long fileSize = 8L + bytes.size() + response.getContentLength();
+            if (fileSize > maxSize)
+                return false;
+
+            temp = Files.createTempFile(directory, "store", ".tmp");
+            try (FileChannel channel = FileChannel.open(temp, StandardOpenOption.WRITE))
+            {
+                ByteBuffer header = ByteBuffer.allocate(8).putInt(MAGIC).putInt(bytes.size()).flip();
+                write(channel, header);
+                write(channel, ByteBuffer.wrap(bytes.toByteArray()));
+                write(channel, response.getContent());
+            }
+
+            try (AutoLock l = lock.lock())
+            {
+                Files.move(temp, path, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+                Entry old = entries.put(key, new Entry(path, fileSize));
+                if (old != null)
+                    size -= old.size;
+                size += fileSize;
+                evict();
+                return entries.containsKey(key);
+            }
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not store {} in {}", key, path, x);
+            if (temp != null)
+                delete(temp);
+            return false;
+        }
+    }
+
+    private static void write(FileChannel channel, ByteBuffer buffer) throws IOException
+    {
+        while (buffer.hasRemaining())
+        {
+            channel.write(buffer);
+        }
+    }
+
+    private void evict()
+    {
+        assert lock.isHeldByCurrentThread();
+        Iterator<Entry> iterator = entries.values().iterator();
+        while (size > maxSize && iterator.hasNext())
+        {
+            Entry eldest = iterator.next();
+            iterator.remove();
+            size -= eldest.size;
+            delete(eldest.path);
+        }
+    }
+
+    @Override
+    public boolean remove(String key)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            Entry entry = entries.remove(key);
+            if (entry == null)
+                return false;
+            size -= entry.size;
+            delete(entry.path);
+            return true;
+        }
+    }
+
+    @Override
+    public void clear()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            entries.values().forEach(entry -> delete(entry.path));
+            entries.clear();
+            size = 0;
+        }
+    }
+
+    @Override
+    @ManagedAttribute("The number of stored responses")
+    public int size()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return entries.size();
+        }
+    }
+
+    private Path pathOf(String key)
+    {
+        try
+        {
+            MessageDigest digest = MessageDigest.getInstance("SHA-256");
+            byte[] hash = digest.digest(key.getBytes(StandardCharsets.UTF_8));
+            return directory.resolve(StringUtil.toHexString(hash) + SUFFIX);
+        }
+        catch (NoSuchAlgorithmException x)
+        {
+            throw new IllegalStateException(x);
+        }
+    }
+
+    private long lastModified(Path path)
+    {
+        try
+        {
+            return Files.getLastModifiedTime(path).toMillis();
+        }
+        catch (IOException x)
+        {
+            return 0;
+        }
+    }
+
+    private static void delete(Path path)
+    {
+        try
+        {
+            Files.deleteIfExists(path);
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not delete {}", path, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,entries=%d,size=%d/%d]", getClass().getSimpleName(), hashCode(), directory, size(), getSize(), maxSize);
+    }
+
+    private static class Entry
+    {
+        private final Path path;
+        private final long size;
+        private volatile Loaded loaded;
+
+        private Entry(Path path, long size)
+        {
+            this.path = path;
+            this.size = size;
+        }
+
+        private Loaded load() throws IOException
+        {
+            Loaded result = loaded;
+            if (result != null)
+                return result;
+
+            MappedByteBuffer buffer;
+            try (FileChannel channel = FileChannel.open(path, StandardOpenOption.READ))
+            {
+                buffer = channel.map(FileChannel.MapMode.READ_ONLY, 0, channel.size());
+            }
+            if (buffer.remaining() < 8 || buffer.getInt() != MAGIC)
+                throw new IOException("Invalid cache file " + path);
+            int length = buffer.getInt();
+            if (length < 0 || length > buffer.remaining())
+                throw new IOException("Invalid cache file " + path);
+            byte[] bytes = new byte[length];
+            buffer.get(bytes);
+
+            try (DataInputStream metaData = new DataInputStream(new ByteArrayInputStream(bytes)))
+            {
+                String key = metaData.readUTF();
+                int status = metaData.readInt();
+                long requestTime = metaData.readLong();
+                long responseTime = metaData.readLong();
+                int count = metaData.readInt();
+                HttpFields.Mutable headers = HttpFields.build(count);
+                for (int i = 0; i < count; ++i)
+                {
+                    headers.add(new HttpField(metaData.readUTF(), metaData.readUTF()));
+                }
+                CachedResponse response = new CachedResponse(status, headers, buffer.slice(), requestTime, responseTime);
+                return loaded = new Loaded(key, response);
+            }
+        }
+    }
+
+    private static class Loaded
+    {
+        private final String key;
+        private final CachedResponse response;
+
+        private Loaded(String key, CachedResponse response)
+        {
+            this.key = key;
+            this.response = response;
+        }
+    }
+}
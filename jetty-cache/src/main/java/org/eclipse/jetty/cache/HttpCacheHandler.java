@@ -0,0 +1,929 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.EnumSet;
+import java.util.List;
+import java.util.Locale;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.DispatcherType;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.DateGenerator;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.QuotedCSV;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that caches the responses of the wrapped handler,
+ * implementing the semantics of RFC 9111, so that Jetty may act as a caching
+ * reverse proxy, for example in front of a proxy servlet.</p>
+ * <p>Responses are stored in a pluggable {@link HttpCacheStore}, by default a
+ * {@link MemoryCacheStore}, according to their {@code Cache-Control}, {@code Expires}
+ * and {@code Vary} headers; responses without explicit freshness information
+ * are given a heuristic freshness lifetime based on their {@code Last-Modified} header.</p>
+ * <p>Stale responses with validators ({@code ETag} or {@code Last-Modified}) are
+ * revalidated with a conditional request to the wrapped handler, and a {@code 304}
+ * response refreshes the stored response.</p>
+ * <p>The {@code stale-while-revalidate} and {@code stale-if-error} extensions of
+ * RFC 5861 are supported: within the {@code stale-while-revalidate} window a stale
+ * response is served immediately, while it is revalidated in background via an
+ * internal {@link LocalConnector}; within the {@code stale-if-error} window a stale
+ * response is served instead of a {@code 500}, {@code 502}, {@code 503} or {@code 504}
+ * response.</p>
+ * <p>By default this handler behaves as a shared cache: responses marked as
+ * {@code private} or with a {@code Set-Cookie} header are not stored, and requests
+ * with an {@code Authorization} header bypass the cache.
+ * Responses to unsafe requests, such as {@code POST}, invalidate the stored responses
+ * for the same URI.</p>
+ * <p>Range requests, and responses that exceed the {@link #getMaxEntrySize() max entry size},
+ * are not cached.</p>
+ */
+@ManagedObject("HTTP cache handler")
+public class HttpCacheHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HttpCacheHandler.class);
+    private static final String REVALIDATION_HEADER = "Jetty-Cache-Revalidation";
+    private static final EnumSet<HttpHeader> NOT_STORED_HEADERS = EnumSet.of(
+        HttpHeader.CONNECTION,
+        HttpHeader.KEEP_ALIVE,
+        HttpHeader.PROXY_AUTHENTICATE,
+        HttpHeader.PROXY_AUTHORIZATION,
+        HttpHeader.PROXY_CONNECTION,
+        HttpHeader.TE,
+        HttpHeader.TRAILER,
+        HttpHeader.TRANSFER_ENCODING,
+        HttpHeader.UPGRADE,
+        HttpHeader.CONTENT_LENGTH
+    );
+    private static final EnumSet<HttpHeader> NOT_FORWARDED_HEADERS = EnumSet.of(
+        HttpHeader.CONNECTION,
+        HttpHeader.KEEP_ALIVE,
+        HttpHeader.PROXY_CONNECTION,
+        HttpHeader.TE,
+        HttpHeader.UPGRADE,
+        HttpHeader.CONTENT_LENGTH,
+        HttpHeader.TRANSFER_ENCODING,
+        HttpHeader.EXPECT,
+        HttpHeader.IF_MATCH,
+        HttpHeader.IF_NONE_MATCH,
+        HttpHeader.IF_MODIFIED_SINCE,
+        HttpHeader.IF_UNMODIFIED_SINCE,
+        HttpHeader.IF_RANGE,
+        HttpHeader.RANGE
+    );
+    // RFC 9110 section 15.1.
+    private static final Set<Integer> HEURISTICALLY_CACHEABLE = Set.of(200, 203, 204, 206, 300, 301, 308, 404, 405, 410, 414, 501);
+    private static final Set<Integer> STALE_IF_ERROR_STATUSES = Set.of(500, 502, 503, 504);
+    private static final long REVALIDATION_TIMEOUT = TimeUnit.SECONDS.toNanos(30);
+
+    private final ConcurrentMap<String, Variants> variants = new ConcurrentHashMap<>();
+    private final ConcurrentMap<String, Revalidation> revalidations = new ConcurrentHashMap<>();
+    private final ConcurrentMap<String, Long> revalidating = new ConcurrentHashMap<>();
+    private final LongAdder hits = new LongAdder();
+    private final LongAdder misses = new LongAdder();
+    private final LongAdder staleHits = new LongAdder();
+    private final LongAdder revalidated = new LongAdder();
+    private final LongAdder stored = new LongAdder();
+    private final HttpCacheStore store;
+    private boolean shared = true;
+    private int maxEntrySize = 1024 * 1024;
+    private double heuristicFraction = 0.1;
+    private Duration maxHeuristicFreshness = Duration.ofDays(1);
+    private boolean backgroundRevalidation = true;
+    private LocalConnector revalidationConnector;
+
+    public HttpCacheHandler()
+    {
+        this(new MemoryCacheStore());
+    }
+
+    /**
+     * @param store the storage of the cached responses
+     */
+    public HttpCacheHandler(HttpCacheStore store)
+    {
+        this.store = Objects.requireNonNull(store);
+        addBean(store);
+    }
+
+    @ManagedAttribute("The storage of the cached responses")
+    public HttpCacheStore getStore()
+    {
+        return store;
+    }
+
+    @ManagedAttribute("Whether this cache is a shared cache")
+    public boolean isShared()
+    {
+        return shared;
+    }
+
+    /**
+     * @param shared whether this cache is a shared cache, which is the case
+     * when used as a reverse proxy, or a private cache
+     */
+    public void setShared(boolean shared)
+    {
+        this.shared = shared;
+    }
+
+    @ManagedAttribute("The max size in bytes of a cached response content")
+    public int getMaxEntrySize()
+    {
+        return maxEntrySize;
+    }
+
+    public void setMaxEntrySize(int maxEntrySize)
+    {
+        this.maxEntrySize = maxEntrySize;
+    }
+
+    @ManagedAttribute("The fraction of the time since Last-Modified used as heuristic freshness lifetime")
+    public double getHeuristicFraction()
+    {
+        return heuristicFraction;
+    }
+
+    /**
+     * @param heuristicFraction the fraction of the time since {@code Last-Modified}
+     * used as freshness lifetime for responses without explicit freshness information,
+     * or 0 to disable heuristic freshness
+     */
+    public void setHeuristicFraction(double heuristicFraction)
+    {
+        if (heuristicFraction < 0 || heuristicFraction > 1)
+            throw new IllegalArgumentException("Invalid heuristic fraction " + heuristicFraction);
+        this.heuristicFraction = heuristicFraction;
+    }
+
+    @ManagedAttribute("The max heuristic freshness lifetime")
+    public Duration getMaxHeuristicFreshness()
+    {
+        return maxHeuristicFreshness;
+    }
+
+    public void setMaxHeuristicFreshness(Duration maxHeuristicFreshness)
+    {
+        this.maxHeuristicFreshness = Objects.requireNonNull(maxHeuristicFreshness);
+    }
+
+    @ManagedAttribute("Whether stale-while-revalidate responses are revalidated in background")
+    public boolean isBackgroundRevalidation()
+    {
+        return backgroundRevalidation;
+    }
+
+    /**
+     * @param backgroundRevalidation whether responses served within their
+     * {@code stale-while-revalidate} window are revalidated in background
+     */
+    public void setBackgroundRevalidation(boolean backgroundRevalidation)
+    {
+        this.backgroundRevalidation = backgroundRevalidation;
+    }
+
+    @ManagedAttribute("The number of requests served by fresh cached responses")
+    public long getHits()
+    {
+        return hits.sum();
+    }
+
+    @ManagedAttribute("The number of requests served by stale cached responses")
+    public long getStaleHits()
+    {
+        return staleHits.sum();
+    }
+
+    @ManagedAttribute("The number of requests forwarded to the wrapped handler")
+    public long getMisses()
+    {
+        return misses.sum();
+    }
+
+    @ManagedAttribute("The number of cached responses revalidated with a 304 response")
+    public long getRevalidated()
+    {
+        return revalidated.sum();
+    }
+
+    @ManagedAttribute("The number of responses stored")
+    public long getStored()
+    {
+        return stored.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        hits.reset();
+        staleHits.reset();
+        misses.reset();
+        revalidated.reset();
+        stored.reset();
+    }
+
+    @ManagedOperation(value = "Removes all the cached responses", impact = "ACTION")
+    public void clear()
+    {
+        variants.clear();
+        store.clear();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        Server server = getServer();
+        if (backgroundRevalidation && server != null)
+        {
+            revalidationConnector = new LocalConnector(server);
+            addManaged(revalidationConnector);
+        }
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        if (revalidationConnector != null)
+            removeBean(revalidationConnector);
+        revalidationConnector = null;
+        revalidations.clear();
+        revalidating.clear();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.getDispatcherType() != DispatcherType.REQUEST)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        Revalidation revalidation = getRevalidation(baseRequest);
+        String primaryKey = revalidation != null ? revalidation.primaryKey : keyOf(baseRequest);
+        HttpMethod method = HttpMethod.fromString(baseRequest.getMethod());
+        if (method != HttpMethod.GET && method != HttpMethod.HEAD)
+        {
+            // RFC 9111 section 4.4, unsafe methods invalidate the stored responses.
+            if (method == null || !method.isSafe())
+                invalidate(primaryKey);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        HttpFields requestHeaders = baseRequest.getHttpFields();
+        if (requestHeaders.contains(HttpHeader.RANGE) || (isShared() && requestHeaders.contains(HttpHeader.AUTHORIZATION)))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        long now = System.currentTimeMillis();
+        CacheControl requestCacheControl = CacheControl.from(requestHeaders);
+        String key = revalidation != null ? revalidation.key : keyOf(primaryKey, variants.get(primaryKey), requestHeaders);
+        CachedResponse cached = store.get(key);
+        if (revalidation == null && cached != null)
+        {
+            long age = cached.getCurrentAge(now);
+            long lifetime = getFreshnessLifetime(cached);
+            if (isFresh(requestCacheControl, cached, age, lifetime))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Serving fresh {} for {}", cached, key);
+                hits.increment();
+                serve(baseRequest, cached, age);
+                return;
+            }
+
+            if (isStaleWhileRevalidate(requestCacheControl, cached, age, lifetime))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Serving stale {} while revalidating {}", cached, key);
+                staleHits.increment();
+                revalidate(baseRequest, primaryKey, key);
+                serve(baseRequest, cached, age);
+                return;
+            }
+        }
+
+        if (revalidation == null && requestCacheControl.isOnlyIfCached())
+        {
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.GATEWAY_TIMEOUT_504);
+            return;
+        }
+
+        if (revalidation == null)
+            misses.increment();
+
+        // Revalidate the stored response, unless the request is already conditional.
+        boolean conditional = false;
+        if (cached != null && !isConditional(requestHeaders))
+        {
+            String etag = cached.getETag();
+            long lastModified = cached.getLastModified();
+            if (etag != null || lastModified >= 0)
+            {
+                HttpFields.Mutable fields = HttpFields.build(requestHeaders);
+                if (etag != null)
+                    fields.put(HttpHeader.IF_NONE_MATCH, etag);
+                if (lastModified >= 0)
+                    fields.put(HttpHeader.IF_MODIFIED_SINCE, cached.getHeaders().get(HttpHeader.LAST_MODIFIED));
+                baseRequest.setHttpFields(fields);
+                conditional = true;
+            }
+        }
+
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        HttpOutput.Interceptor interceptor = out.getInterceptor();
+        try
+        {
+            out.setInterceptor(new CacheInterceptor(baseRequest, interceptor, primaryKey, cached, conditional, requestCacheControl, now, revalidation));
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (!baseRequest.isHandled() && !baseRequest.isAsyncStarted())
+            {
+                out.setInterceptor(interceptor);
+                if (conditional)
+                    baseRequest.setHttpFields(requestHeaders);
+            }
+        }
+    }
+
+    /**
+     * <p>Returns the primary cache key of the given request, by default the absolute request URI.</p>
+     *
+     * @param request the request
+     * @return the primary cache key
+     */
+    protected String keyOf(Request request)
+    {
+        StringBuilder builder = new StringBuilder(128);
+        builder.append(request.getScheme()).append("://")
+            .append(request.getServerName().toLowerCase(Locale.ENGLISH)).append(':')
+            .append(request.getServerPort())
+            .append(request.getRequestURI());
+        String query = request.getQueryString();
+        if (query != null)
+            builder.append('?').append(query);
+        return builder.toString();
+    }
+
+    private String keyOf(String primaryKey, Variants variants, HttpFields requestHeaders)
+    {
+        if (variants == null || variants.names.isEmpty())
+            return primaryKey;
+        StringBuilder builder = new StringBuilder(primaryKey);
+        for (String name : variants.names)
+        {
+            // Normalize the values, so that semantically equal headers select the same variant.
+            QuotedCSV values = new QuotedCSV(true);
+            requestHeaders.getValuesList(name).forEach(values::addValue);
+            builder.append('\n').append(name).append(':').append(String.join(",", values.getValues()));
+        }
+        return builder.toString();
+    }
+
+    private void invalidate(String primaryKey)
+    {
+        Variants removed = variants.remove(primaryKey);
+        if (removed != null)
+            removed.keys.forEach(store::remove);
+        store.remove(primaryKey);
+    }
+
+    private long getFreshnessLifetime(CachedResponse cached)
+    {
+        long lifetime = cached.getFreshnessLifetime(isShared());
+        if (lifetime >= 0)
+            return lifetime;
+        // RFC 9111 section 4.2.2.
+        long lastModified = cached.getLastModified();
+        if (lastModified < 0 || (!HEURISTICALLY_CACHEABLE.contains(cached.getStatus()) && !cached.getCacheControl().isPublic()))
+            return 0;
+        long heuristic = (long)((cached.getDate() - lastModified) * getHeuristicFraction());
+        return Math.max(0, Math.min(heuristic, getMaxHeuristicFreshness().toMillis()));
+    }
+
+    private boolean mustRevalidate(CacheControl cacheControl)
+    {
+        return cacheControl.isMustRevalidate() || (isShared() && cacheControl.isProxyRevalidate());
+    }
+
+    private boolean isFresh(CacheControl requestCacheControl, CachedResponse cached, long age, long lifetime)
+    {
+        CacheControl cacheControl = cached.getCacheControl();
+        if (cacheControl.isNoCache() || requestCacheControl.isNoCache())
+            return false;
+        long maxAge = requestCacheControl.getMaxAge();
+        if (maxAge >= 0 && age > TimeUnit.SECONDS.toMillis(maxAge))
+            return false;
+        long minFresh = requestCacheControl.getMinFresh();
+        if (minFresh >= 0 && lifetime - age < TimeUnit.SECONDS.toMillis(minFresh))
+            return false;
+        if (lifetime > age)
+            return true;
+        // RFC 9111 section 4.2.4, the client may accept stale responses.
+        long maxStale = requestCacheControl.getMaxStale();
+        if (maxStale < 0 || mustRevalidate(cacheControl))
+            return false;
+        return maxStale == Long.MAX_VALUE || age - lifetime <= TimeUnit.SECONDS.toMillis(maxStale);
+    }
+
+    private boolean isStaleWhileRevalidate(CacheControl requestCacheControl, CachedResponse cached, long age, long lifetime)
+    {
+        CacheControl cacheControl = cached.getCacheControl();
+        if (requestCacheControl.isNoCache() || cacheControl.isNoCache() || mustRevalidate(cacheControl))
+            return false;
+        long staleWhileRevalidate = cacheControl.getStaleWhileRevalidate();
+        if (staleWhileRevalidate < 0)
+            return false;
+        return age - lifetime <= TimeUnit.SECONDS.toMillis(staleWhileRevalidate);
+    }
+
+    private boolean isStaleIfError(CacheControl requestCacheControl, CachedResponse cached, long now)
+    {
+        CacheControl cacheControl = cached.getCacheControl();
+        if (mustRevalidate(cacheControl))
+            return false;
+        long staleIfError = requestCacheControl.getStaleIfError();
+        if (staleIfError < 0)
+            staleIfError = cacheControl.getStaleIfError();
+        if (staleIfError < 0)
+            return false;
+        long staleness = cached.getCurrentAge(now) - getFreshnessLifetime(cached);
+        return staleness <= TimeUnit.SECONDS.toMillis(staleIfError);
+    }
+
+    private static boolean isConditional(HttpFields requestHeaders)
+    {
+        return requestHeaders.contains(HttpHeader.IF_NONE_MATCH) || requestHeaders.contains(HttpHeader.IF_MODIFIED_SINCE) ||
+            requestHeaders.contains(HttpHeader.IF_MATCH) || requestHeaders.contains(HttpHeader.IF_UNMODIFIED_SINCE);
+    }
+
+    private static boolean isNotModified(HttpFields requestHeaders, CachedResponse cached)
+    {
+        if (cached.getStatus() != HttpStatus.OK_200)
+            return false;
+        List<String> ifNoneMatch = requestHeaders.getCSV(HttpHeader.IF_NONE_MATCH, true);
+        if (!ifNoneMatch.isEmpty())
+        {
+            String etag = cached.getETag();
+            if (etag == null)
+                return false;
+            // RFC 9110 section 13.1.2, weak comparison.
+            String opaque = etag.startsWith("W/") ? etag.substring(2) : etag;
+            for (String value : ifNoneMatch)
+            {
+                if ("*".equals(value) || opaque.equals(value.startsWith("W/") ? value.substring(2) : value))
+                    return true;
+            }
+            return false;
+        }
+        long lastModified = cached.getLastModified();
+        if (lastModified < 0)
+            return false;
+        try
+        {
+            long ifModifiedSince = requestHeaders.getDateField(HttpHeader.IF_MODIFIED_SINCE.asString());
+            return ifModifiedSince >= 0 && lastModified <= ifModifiedSince;
+        }
+        catch (IllegalArgumentException x)
+        {
+            return false;
+        }
+    }
+
+    private void serve(Request baseRequest, CachedResponse cached, long age) throws IOException
+    {
+        baseRequest.setHandled(true);
+        boolean notModified = isNotModified(baseRequest.getHttpFields(), cached);
+        Response baseResponse = baseRequest.getResponse();
+        applyHeaders(baseResponse, cached, age, notModified);
+        if (notModified || HttpMethod.HEAD.is(baseRequest.getMethod()) || cached.getContentLength() == 0)
+            return;
+        baseResponse.getHttpOutput().write(cached.getContent());
+    }
+
+    private void applyHeaders(Response response, CachedResponse cached, long age, boolean notModified)
+    {
+        HttpFields.Mutable fields = response.getHttpFields();
+        response.setStatus(notModified ? HttpStatus.NOT_MODIFIED_304 : cached.getStatus());
+        for (HttpField field : cached.getHeaders())
+        {
+            fields.remove(field.getName());
+        }
+        for (HttpField field : cached.getHeaders())
+        {
+            HttpHeader header = field.getHeader();
+            if (header == HttpHeader.CONTENT_TYPE)
+            {
+                if (!notModified)
+                    response.setContentType(field.getValue());
+                continue;
+            }
+            if (notModified && (header == HttpHeader.CONTENT_ENCODING || header == HttpHeader.CONTENT_LANGUAGE))
+                continue;
+            fields.add(field);
+        }
+        // Only the header is set, as the content of the wrapped
+        // handler may be discarded and replaced by the cached content.
+        response.setContentLength(-1);
+        if (!notModified)
+            fields.putLongField(HttpHeader.CONTENT_LENGTH, cached.getContentLength());
+        fields.put(HttpHeader.AGE, String.valueOf(TimeUnit.MILLISECONDS.toSeconds(Math.max(0, age))));
+    }
+
+    private void revalidate(Request baseRequest, String primaryKey, String key)
+    {
+        LocalConnector connector = revalidationConnector;
+        if (connector == null)
+            return;
+
+        long now = NanoTime.now();
+        Long previous = revalidating.putIfAbsent(key, now);
+        if (previous != null)
+        {
+            if (NanoTime.elapsed(previous, now) < REVALIDATION_TIMEOUT || !revalidating.replace(key, previous, now))
+                return;
+        }
+
+        String token = Long.toHexString(ThreadLocalRandom.current().nextLong()) + Long.toHexString(now);
+        revalidations.put(token, new Revalidation(primaryKey, key));
+        StringBuilder builder = new StringBuilder(512);
+        builder.append(HttpMethod.GET.asString()).append(' ')
+            .append(baseRequest.getHttpURI().getPathQuery())
+            .append(" HTTP/1.1\r\n");
+        for (HttpField field : baseRequest.getHttpFields())
+        {
+            HttpHeader header = field.getHeader();
+            if (header != null && NOT_FORWARDED_HEADERS.contains(header))
+                continue;
+            if (REVALIDATION_HEADER.equalsIgnoreCase(field.getName()))
+                continue;
+            builder.append(field.getName()).append(": ").append(field.getValue()).append("\r\n");
+        }
+        builder.append(REVALIDATION_HEADER).append(": ").append(token).append("\r\n");
+        builder.append("Connection: close\r\n\r\n");
+
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Revalidating in background {}", key);
+            connector.executeRequest(builder.toString());
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not revalidate {}", key, x);
+            revalidations.remove(token);
+            revalidating.remove(key);
+        }
+    }
+
+    private Revalidation getRevalidation(Request baseRequest)
+    {
+        LocalConnector connector = revalidationConnector;
+        if (connector == null || baseRequest.getHttpChannel().getConnector() != connector)
+            return null;
+        String token = baseRequest.getHeader(REVALIDATION_HEADER);
+        if (token == null)
+            return null;
+        Revalidation revalidation = revalidations.remove(token);
+        if (revalidation == null)
+            return null;
+        HttpFields.Mutable fields = HttpFields.build(baseRequest.getHttpFields());
+        fields.remove(REVALIDATION_HEADER);
+        baseRequest.setHttpFields(fields);
+        return revalidation;
+    }
+
+    private boolean isStorable(Request request, Response response, CacheControl requestCacheControl, CacheControl cacheControl)
+    {
+        // RFC 9111 section 3.
+        if (!HttpMethod.GET.is(request.getMethod()))
+            return false;
+        int status = response.getStatus();
+        if (status < HttpStatus.OK_200 || status == HttpStatus.PARTIAL_CONTENT_206 || status == HttpStatus.NOT_MODIFIED_304)
+            return false;
+        if (requestCacheControl.isNoStore() || cacheControl.isNoStore())
+            return false;
+        HttpFields fields = response.getHttpFields();
+        if (isShared() && (cacheControl.isPrivate() || fields.contains(HttpHeader.SET_COOKIE)))
+            return false;
+        if (fields.contains(HttpHeader.VARY, "*") || fields.contains(HttpHeader.CONTENT_RANGE))
+            return false;
+        long contentLength = response.getContentLength();
+        if (contentLength > getMaxEntrySize())
+            return false;
+        return cacheControl.isPublic() ||
+            cacheControl.getMaxAge() >= 0 ||
+            (isShared() && cacheControl.getSMaxAge() >= 0) ||
+            fields.contains(HttpHeader.EXPIRES) ||
+            HEURISTICALLY_CACHEABLE.contains(status);
+    }
+
+    private void store(String primaryKey, Request request, CachedResponse cached)
+    {
+        List<String> names = new ArrayList<>();
+        for (String vary : cached.getHeaders().getCSV(HttpHeader.VARY, false))
+        {
+            names.add(vary.toLowerCase(Locale.ENGLISH));
+        }
+        names.sort(String::compareTo);
+        Variants current = variants.compute(primaryKey, (k, v) ->
+        {
+            if (v != null && v.names.equals(names))
+                return v;
+            // The Vary header changed, the previous variants are obsolete.
+            if (v != null)
+                v.keys.forEach(store::remove);
+            else
+                store.remove(primaryKey);
+            return names.isEmpty() ? null : new Variants(names);
+        });
+        String key = keyOf(primaryKey, current, request.getHttpFields());
+        if (current != null)
+            current.keys.add(key);
+        if (store.put(key, cached))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Stored {} for {}", cached, key);
+            stored.increment();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[shared=%b,%s]", getClass().getSimpleName(), hashCode(), shared, store);
+    }
+
+    private static class Variants
+    {
+        private final List<String> names;
+        private final Set<String> keys = ConcurrentHashMap.newKeySet();
+
+        private Variants(List<String> names)
+        {
+            this.names = names;
+        }
+    }
+
+    private static class Revalidation
+    {
+        private final String primaryKey;
+        private final String key;
+
+        private Revalidation(String primaryKey, String key)
+        {
+            this.primaryKey = primaryKey;
+            this.key = key;
+        }
+    }
+
+    private enum State
+    {
+        IDLE, STORING, FORWARDING, REPLACED
+    }
+
+    /**
+     * <p>Intercepts the response content of the wrapped handler to store the
+     * response, or to replace it with the cached response.</p>
+     */
+    private class CacheInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request request;
+        private final HttpOutput.Interceptor next;
+        private final String primaryKey;
+        private final CachedResponse cached;
+        private final boolean conditional;
+        private final CacheControl requestCacheControl;
+        private final long requestTime;
+        private final Revalidation revalidation;
+        private State state = State.IDLE;
+        private long responseTime;
+        private ByteBuffer content;
+        private CachedResponse replacement;
+
+        private CacheInterceptor(Request request, HttpOutput.Interceptor next, String primaryKey, CachedResponse cached, boolean conditional, CacheControl requestCacheControl, long requestTime, Revalidation revalidation)
+        {
+            this.request = request;
+            this.next = next;
+            this.primaryKey = primaryKey;
+            this.cached = cached;
+            this.conditional = conditional;
+            this.requestCacheControl = requestCacheControl;
+            this.requestTime = requestTime;
+            this.revalidation = revalidation;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return next;
+        }
+
+        @Override
+        public void write(ByteBuffer buffer, boolean last, Callback callback)
+        {
+            if (state == State.IDLE)
+                commit();
+
+            switch (state)
+            {
+                case REPLACED:
+                {
+                    if (last)
+                        complete();
+                    CachedResponse replacement = this.replacement;
+                    this.replacement = null;
+                    if (replacement == null)
+                    {
+                        // The content of the wrapped handler is discarded.
+                        callback.succeeded();
+                    }
+                    else
+                    {
+                        boolean content = !isNotModified(request.getHttpFields(), replacement) && !HttpMethod.HEAD.is(request.getMethod());
+                        next.write(content ? replacement.getContent() : BufferUtil.EMPTY_BUFFER, true, callback);
+                    }
+                    break;
+                }
+                case STORING:
+                {
+                    int length = BufferUtil.length(buffer);
+                    if (BufferUtil.space(content) < length)
+                    {
+                        long capacity = Math.max(content.capacity() * 2L, (long)content.position() + length);
+                        if (capacity > getMaxEntrySize())
+                        {
+                            if (LOG.isDebugEnabled())
+                                LOG.debug("Not storing {}, content too large", primaryKey);
+                            state = State.FORWARDING;
+                            content = null;
+                        }
+                        else
+                        {
+                            ByteBuffer newContent = ByteBuffer.allocate((int)capacity);
+                            newContent.put(content.flip());
+                            content = newContent;
+                        }
+                    }
+                    if (state == State.STORING)
+                    {
+                        if (length > 0)
+                            content.put(buffer.slice());
+                        if (last)
+                            store(primaryKey, request, new CachedResponse(request.getResponse().getStatus(), storedHeaders(), content.flip(), requestTime, responseTime));
+                    }
+                    if (last)
+                        complete();
+                    next.write(buffer, last, callback);
+                    break;
+                }
+                default:
+                {
+                    if (last)
+                        complete();
+                    next.write(buffer, last, callback);
+                    break;
+                }
+            }
+        }
+
+        @Override
+        public void resetBuffer()
+        {
+            if (state == State.STORING)
+                content.clear();
+            HttpOutput.Interceptor.super.resetBuffer();
+        }
+
+        private void commit()
+        {
+            responseTime = System.currentTimeMillis();
+            Response response = request.getResponse();
+            int status = response.getStatus();
+            HttpFields fields = response.getHttpFields();
+
+            if (status == HttpStatus.NOT_MODIFIED_304 && conditional)
+            {
+                String etag = fields.get(HttpHeader.ETAG);
+                if (etag == null || etag.equals(cached.getETag()))
+                {
+                    revalidated.increment();
+                    CachedResponse updated = cached.update(fields, requestTime, responseTime);
+                    store(primaryKey, request, updated);
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Revalidated {} for {}", updated, primaryKey);
+                    if (revalidation != null)
+                        state = State.FORWARDING;
+                    else
+                        replace(updated);
+                    return;
+                }
+            }
+
+            if (STALE_IF_ERROR_STATUSES.contains(status) && cached != null && revalidation == null && isStaleIfError(requestCacheControl, cached, responseTime))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Serving stale {} for {} after {} response", cached, primaryKey, status);
+                staleHits.increment();
+                replace(cached);
+                return;
+            }
+
+            CacheControl cacheControl = CacheControl.from(fields);
+            if (isStorable(request, response, requestCacheControl, cacheControl))
+            {
+                state = State.STORING;
+                long contentLength = response.getContentLength();
+                content = ByteBuffer.allocate((int)Math.min(getMaxEntrySize(), contentLength >= 0 ? contentLength : 4096));
+                return;
+            }
+
+            // A response that cannot be stored replaces the stored one.
+            if (cached != null && HttpMethod.GET.is(request.getMethod()) && status >= HttpStatus.OK_200 &&
+                status != HttpStatus.NOT_MODIFIED_304 && !STALE_IF_ERROR_STATUSES.contains(status))
+                invalidate(primaryKey);
+            state = State.FORWARDING;
+        }
+
+        private void replace(CachedResponse response)
+        {
+            state = State.REPLACED;
+            replacement = response;
+            Response baseResponse = request.getResponse();
+            baseResponse.getHttpFields().clear();
+            boolean notModified = isNotModified(request.getHttpFields(), response);
+            applyHeaders(baseResponse, response, response.getCurrentAge(System.currentTimeMillis()), notModified);
+        }
+
+        private HttpFields storedHeaders()
+        {
+            HttpFields.Mutable fields = HttpFields.build();
+            for (HttpField field : request.getResponse().getHttpFields())
+            {
+                HttpHeader header = field.getHeader();
+                if (header != null && NOT_STORED_HEADERS.contains(header))
+                    continue;
+                fields.add(field);
+            }
+            if (!fields.contains(HttpHeader.DATE))
+                fields.put(HttpHeader.DATE, DateGenerator.formatDate(responseTime));
+            return fields;
+        }
+
+        private void complete()
+        {
+            if (revalidation != null)
+                revalidating.remove(revalidation.key);
+        }
+    }
+}
@@ -0,0 +1,58 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+/**
+ * <p>The storage of the responses cached by {@link HttpCacheHandler}.</p>
+ * <p>Implementations must be thread-safe, and may evict responses at any time,
+ * for example to honor a size limit.
+ * Implementations that are {@link org.eclipse.jetty.util.component.LifeCycle}s
+ * are started and stopped together with the {@link HttpCacheHandler}.</p>
+ *
+ * @see MemoryCacheStore
+ * @see FileCacheStore
+ */
+public interface HttpCacheStore
+{
+    /**
+     * @param key the cache key
+     * @return the response stored with the given key, or null if there is no such response
+     */
+    CachedResponse get(String key);
+
+    /**
+     * <p>Stores the given response, replacing any response stored with the same key.</p>
+     *
+     * @param key the cache key
+     * @param response the response to store
+     * @return whether the response was stored
+     */
+    boolean put(String key, CachedResponse response);
+
+    /**
+     * @param key the cache key
+     * @return whether a response was removed
+     */
+    boolean remove(String key);
+
+    /**
+     * <p>Removes all the stored responses.</p>
+     */
+    void clear();
+
+    /**
+     * @return the number of stored responses
+     */
+    int size();
+}
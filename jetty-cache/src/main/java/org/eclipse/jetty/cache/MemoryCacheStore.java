@@ -0,0 +1,190 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.nio.ByteBuffer;
+import java.util.Iterator;
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A {@link HttpCacheStore} that keeps the responses in memory, either in the
+ * heap (the default) or off-heap, in direct {@link ByteBuffer}s.</p>
+ * <p>When the max size or the max number of entries is exceeded,
+ * the least recently used responses are evicted.</p>
+ */
+@ManagedObject("In-memory HTTP cache store")
+public class MemoryCacheStore implements HttpCacheStore
+{
+    private final AutoLock lock = new AutoLock();
+    private final Map<String, Entry> entries = new LinkedHashMap<>(16, 0.75F, true);
+    private final long maxSize;
+    private final int maxEntries;
+    private final boolean direct;
+    private long size;
+
+    public MemoryCacheStore()
+    {
+        this(64 * 1024 * 1024, 10_000, false);
+    }
+
+    /**
+     * @param maxSize the max size in bytes of the stored responses
+     * @param maxEntries the max number of stored responses
+     * @param direct whether the response content is stored off-heap
+     */
+    public MemoryCacheStore(@Name("maxSize") long maxSize, @Name("maxEntries") int maxEntries, @Name("direct") boolean direct)
+    {
+        this.maxSize = maxSize;
+        this.maxEntries = maxEntries;
+        this.direct = direct;
+    }
+
+    @ManagedAttribute("The max size in bytes of the stored responses")
+    public long getMaxSize()
+    {
+        return maxSize;
+    }
+
+    @ManagedAttribute("The max number of stored responses")
+    public int getMaxEntries()
+    {
+        return maxEntries;
+    }
+
+    @ManagedAttribute("Whether the response content is stored off-heap")
+    public boolean isDirect()
+    {
+        return direct;
+    }
+
+    @ManagedAttribute("The size in bytes of the stored responses")
+    public long getSize()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return size;
+        }
+    }
+
+    @Override
+    public CachedResponse get(String key)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            Entry entry = entries.get(key);
+            return entry == null ? null : entry.response;
+        }
+    }
+
+    @Override
+    public boolean put(String key, CachedResponse response)
+    {
+        long entrySize = sizeOf(key, response);
+        if (entrySize > maxSize)
+            return false;
+
+        CachedResponse stored = response;
+        if (direct != response.getContent().isDirect())
+        {
+            ByteBuffer content = direct ? ByteBuffer.allocateDirect(response.getContentLength()) : ByteBuffer.allocate(response.getContentLength());
+            content.put(response.getContent()).flip();
+            stored = new CachedResponse(response.getStatus(), response.getHeaders(), content, response.getRequestTime(), response.getResponseTime());
+        }
+
+        try (AutoLock l = lock.lock())
+        {
+            Entry old = entries.put(key, new Entry(stored, entrySize));
+            if (old != null)
+                size -= old.size;
+            size += entrySize;
+
+            Iterator<Entry> iterator = entries.values().iterator();
+            while ((size > maxSize || entries.size() > maxEntries) && iterator.hasNext())
+            {
+                Entry eldest = iterator.next();
+                iterator.remove();
+                size -= eldest.size;
+            }
+            return entries.containsKey(key);
+        }
+    }
+
+    @Override
+    public boolean remove(String key)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            Entry entry = entries.remove(key);
+            if (entry == null)
+                return false;
+            size -= entry.size;
+            return true;
+        }
+    }
+
+    @Override
+    public void clear()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            entries.clear();
+            size = 0;
+        }
+    }
+
+    @Override
+    @ManagedAttribute("The number of stored responses")
+    public int size()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return entries.size();
+        }
+    }
+
+    private static long sizeOf(String key, CachedResponse response)
+    {
+        long size = key.length() + response.getContentLength();
+        for (HttpField field : response.getHeaders())
+        {
+            size += field.getName().length() + field.getValue().length() + 4;
+        }
+        return size;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[direct=%b,entries=%d,size=%d/%d]", getClass().getSimpleName(), hashCode(), direct, size(), getSize(), maxSize);
+    }
+
+    private static class Entry
+    {
+        private final CachedResponse response;
+        private final long size;
+
+        private Entry(CachedResponse response, long size)
+        {
+            this.response = response;
+            this.size = size;
+        }
+    }
+}
@@ -0,0 +1,278 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class HttpCacheHandlerTest
+{
+    private final AtomicInteger requests = new AtomicInteger();
+    private Server server;
+    private LocalConnector connector;
+    private HttpCacheHandler cacheHandler;
+
+    private void start(Responder responder) throws Exception
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        cacheHandler = new HttpCacheHandler();
+        cacheHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                responder.respond(requests.incrementAndGet(), request, response);
+            }
+        });
+        server.setHandler(cacheHandler);
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (server != null)
+            server.stop();
+    }
+
+    private HttpTester.Response request(String method, String headers) throws Exception
+    {
+        String request = method + " /path HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    private static void write(HttpServletResponse response, String content) throws IOException
+    {
+        response.setContentType("text/plain");
+        response.getOutputStream().write(content.getBytes(StandardCharsets.UTF_8));
+    }
+
+    @Test
+    public void testFreshResponseServedFromCache() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+            write(response, "hello" + count);
+        });
+
+        HttpTester.Response response = request("GET", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello1"));
+
+        response = request("GET", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello1"));
+        assertThat(response.get(HttpHeader.AGE), notNullValue());
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), is("text/plain"));
+
+        // HEAD requests are served from the GET response.
+        response = request("HEAD", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getLongField(HttpHeader.CONTENT_LENGTH), is(6L));
+
+        assertThat(requests.get(), is(1));
+        assertThat(cacheHandler.getHits(), is(2L));
+        assertThat(cacheHandler.getMisses(), is(1L));
+    }
+
+    @Test
+    public void testVary() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+            response.setHeader(HttpHeader.VARY.asString(), "Accept-Language");
+            write(response, request.getHeader("Accept-Language"));
+        });
+
+        assertThat(request("GET", "Accept-Language: en\r\n").getContent(), is("en"));
+        assertThat(request("GET", "Accept-Language: fr\r\n").getContent(), is("fr"));
+        assertThat(request("GET", "Accept-Language: en\r\n").getContent(), is("en"));
+        assertThat(request("GET", "Accept-Language: fr\r\n").getContent(), is("fr"));
+
+        assertThat(requests.get(), is(2));
+    }
+
+    @Test
+    public void testRevalidation() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-cache");
+            response.setHeader(HttpHeader.ETAG.asString(), "\"v1\"");
+            if ("\"v1\"".equals(request.getHeader("If-None-Match")))
+                response.setStatus(HttpStatus.NOT_MODIFIED_304);
+            else
+                write(response, "hello");
+        });
+
+        assertThat(request("GET", "").getContent(), is("hello"));
+
+        // The second request is revalidated with the wrapped handler.
+        HttpTester.Response response = request("GET", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello"));
+        assertThat(requests.get(), is(2));
+        assertThat(cacheHandler.getRevalidated(), is(1L));
+
+        // A conditional request of the client receives the 304.
+        response = request("GET", "If-None-Match: \"v1\"\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.NOT_MODIFIED_304));
+        assertThat(response.get(HttpHeader.ETAG), is("\"v1\""));
+    }
+
+    @Test
+    public void testNotStorableResponses() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            String cacheControl = request.getParameter("cc");
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), cacheControl);
+            write(response, "hello" + count);
+        });
+
+        for (String cacheControl : new String[]{"no-store", "private"})
+        {
+            requests.set(0);
+            String request = "GET /path?cc=" + cacheControl + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+            HttpTester.parseResponse(connector.getResponse(request));
+            HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(request));
+            assertThat(response.getContent(), is("hello2"));
+        }
+        assertThat(cacheHandler.getStored(), is(0L));
+    }
+
+    @Test
+    public void testUnsafeMethodInvalidates() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+            write(response, "hello" + count);
+        });
+
+        assertThat(request("GET", "").getContent(), is("hello1"));
+        assertThat(request("GET", "").getContent(), is("hello1"));
+        assertThat(request("POST", "Content-Length: 0\r\n").getContent(), is("hello2"));
+        assertThat(request("GET", "").getContent(), is("hello3"));
+    }
+
+    @Test
+    public void testRequestNoCacheAndOnlyIfCached() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+            write(response, "hello" + count);
+        });
+
+        assertThat(request("GET", "Cache-Control: only-if-cached\r\n").getStatus(), is(HttpStatus.GATEWAY_TIMEOUT_504));
+        assertThat(request("GET", "").getContent(), is("hello1"));
+        assertThat(request("GET", "Cache-Control: no-cache\r\n").getContent(), is("hello2"));
+        assertThat(request("GET", "Cache-Control: only-if-cached\r\n").getContent(), is("hello2"));
+    }
+
+    @Test
+    public void testStaleIfError() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            if (count == 1)
+            {
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=0, stale-if-error=60");
+                write(response, "hello");
+            }
+            else
+            {
+                response.setStatus(HttpStatus.SERVICE_UNAVAILABLE_503);
+                write(response, "unavailable");
+            }
+        });
+
+        assertThat(request("GET", "").getContent(), is("hello"));
+
+        HttpTester.Response response = request("GET", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello"));
+        assertThat(requests.get(), is(2));
+        assertThat(cacheHandler.getStaleHits(), is(1L));
+    }
+
+    @Test
+    public void testStaleWhileRevalidate() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=0, stale-while-revalidate=60");
+            write(response, "hello" + count);
+        });
+
+        assertThat(request("GET", "").getContent(), is("hello1"));
+
+        // The stale response is served, and revalidated in background.
+        HttpTester.Response response = request("GET", "");
+        assertThat(response.getContent(), is("hello1"));
+        long timeout = System.nanoTime() + TimeUnit.SECONDS.toNanos(5);
+        while (cacheHandler.getStored() < 2 && System.nanoTime() < timeout)
+        {
+            Thread.sleep(10);
+        }
+        assertThat(cacheHandler.getStored(), is(2L));
+        assertThat(requests.get(), is(2));
+
+        assertThat(request("GET", "").getContent(), is("hello2"));
+    }
+
+    @Test
+    public void testAuthorizationBypassesSharedCache() throws Exception
+    {
+        start((count, request, response) ->
+        {
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+            write(response, "hello" + count);
+        });
+
+        assertThat(request("GET", "Authorization: Bearer token\r\n").getContent(), is("hello1"));
+        assertThat(request("GET", "Authorization: Bearer token\r\n").getContent(), is("hello2"));
+        assertThat(cacheHandler.getStore().size(), is(0));
+    }
+
+    @FunctionalInterface
+    private interface Responder
+    {
+        void respond(int count, HttpServletRequest request, HttpServletResponse response) throws IOException;
+    }
+}
@@ -0,0 +1,161 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.cache;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Path;
+import java.util.stream.Stream;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.MethodSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class HttpCacheStoreTest
+{
+    public WorkDir workDir;
+
+    public static Stream<String> stores()
+    {
+        return Stream.of("heap", "direct", "file");
+    }
+
+    private HttpCacheStore newStore(String type, long maxSize) throws Exception
+    {
+        switch (type)
+        {
+            case "heap":
+                return new MemoryCacheStore(maxSize, 100, false);
+            case "direct":
+                return new MemoryCacheStore(maxSize, 100, true);
+            case "file":
+                FileCacheStore store = new FileCacheStore(workDir.getEmptyPathDir(), maxSize);
+                store.start();
+                return store;
+            default:
+                throw new IllegalArgumentException(type);
+        }
+    }
+
+    private static CachedResponse newResponse(String content)
+    {
+        HttpFields fields = HttpFields.build()
+            .put(HttpHeader.CONTENT_TYPE, "text/plain")
+            .put(HttpHeader.ETAG, "\"" + content + "\"");
+        ByteBuffer buffer = BufferUtil.toBuffer(content, StandardCharsets.UTF_8);
+        return new CachedResponse(200, fields, buffer, 1000, 2000);
+    }
+
+    @ParameterizedTest
+    @MethodSource("stores")
+    public void testPutGetRemove(String type) throws Exception
+    {
+        HttpCacheStore store = newStore(type, 1024);
+        try
+        {
+            assertThat(store.put("key", newResponse("hello")), is(true));
+            CachedResponse response = store.get("key");
+            assertThat(response, notNullValue());
+            assertThat(response.getStatus(), is(200));
+            assertThat(response.getETag(), is("\"hello\""));
+            assertThat(response.getResponseTime(), is(2000L));
+            assertThat(BufferUtil.toString(response.getContent()), is("hello"));
+            assertThat(store.size(), is(1));
+
+            assertThat(store.remove("key"), is(true));
+            assertThat(store.get("key"), nullValue());
+            assertThat(store.size(), is(0));
+        }
+        finally
+        {
+            LifeCycle.stop(store);
+        }
+    }
+
+    @ParameterizedTest
+    @MethodSource("stores")
+    public void testEviction(String type) throws Exception
+    {
+        // Size the store to hold two responses.
+        HttpCacheStore store = newStore(type, 1024);
+        store.put("a", newResponse("aaaa"));
+        long entrySize = sizeOf(store);
+        LifeCycle.stop(store);
+
+        store = newStore(type, entrySize * 2 + entrySize / 2);
+        try
+        {
+            assertThat(store.put("a", newResponse("aaaa")), is(true));
+            assertThat(store.put("b", newResponse("bbbb")), is(true));
+            // Access "a" so that "b" is the least recently used.
+            assertThat(store.get("a"), notNullValue());
+            assertThat(store.put("c", newResponse("cccc")), is(true));
+
+            assertThat(store.get("a"), notNullValue());
+            assertThat(store.get("b"), nullValue());
+            assertThat(store.get("c"), notNullValue());
+            // Responses larger than the store are rejected.
+            assertThat(store.put("d", newResponse("d".repeat((int)entrySize * 3))), is(false));
+        }
+        finally
+        {
+            LifeCycle.stop(store);
+        }
+    }
+
+    private static long sizeOf(HttpCacheStore store)
+    {
+        if (store instanceof MemoryCacheStore)
+            return ((MemoryCacheStore)store).getSize();
+        return ((FileCacheStore)store).getSize();
+    }
+
+    @Test
+    public void testFileStoreRestart() throws Exception
+    {
+        Path directory = workDir.getEmptyPathDir();
+        FileCacheStore store = new FileCacheStore(directory, 1024);
+        store.start();
+        store.put("key", newResponse("hello"));
+        store.stop();
+
+        store = new FileCacheStore(directory, 1024);
+        store.start();
+        try
+        {
+            assertThat(store.size(), is(1));
+            CachedResponse response = store.get("key");
+            assertThat(response, notNullValue());
+            assertThat(response.getHeaders().get(HttpHeader.CONTENT_TYPE), is("text/plain"));
+            assertThat(BufferUtil.toString(response.getContent()), is("hello"));
+        }
+        finally
+        {
+            store.stop();
+        }
+    }
+}
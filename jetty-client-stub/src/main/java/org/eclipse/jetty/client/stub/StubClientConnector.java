@@ -0,0 +1,87 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.net.SocketAddress;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.util.Promise;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ClientConnector} that opens in-memory connections to a {@link StubConnector}
+ * instead of opening sockets, whatever is the address it is asked to connect to.</p>
+ * <p>It can be used with any client transport based on {@link ClientConnector}, for example:</p>
+ * <pre>
+ * StubClientConnector clientConnector = new StubClientConnector(stubConnector);
+ * HTTP2Client http2Client = new HTTP2Client(clientConnector);
+ * HttpClientTransport transport = new HttpClientTransportDynamic(clientConnector,
+ *     HttpClientConnectionFactory.HTTP11, new ClientConnectionFactoryOverHTTP2.HTTP2(http2Client));
+ * </pre>
+ */
+public class StubClientConnector extends ClientConnector
+{
+    private static final Logger LOG = LoggerFactory.getLogger(StubClientConnector.class);
+
+    private final StubConnector connector;
+
+    public StubClientConnector(StubConnector connector)
+    {
+        this.connector = Objects.requireNonNull(connector);
+    }
+
+    /**
+     * @return the server connector this client connector connects to
+     */
+    public StubConnector getStubConnector()
+    {
+        return connector;
+    }
+
+    @Override
+    public void connect(SocketAddress address, Map<String, Object> context)
+    {
+        try
+        {
+            if (context == null)
+                context = new ConcurrentHashMap<>();
+            context.put(ClientConnector.CLIENT_CONNECTOR_CONTEXT_KEY, this);
+            context.putIfAbsent(REMOTE_SOCKET_ADDRESS_CONTEXT_KEY, address);
+
+            StubEndPoint endPoint = connector.connect(address);
+            endPoint.setIdleTimeout(getIdleTimeout().toMillis());
+            if (LOG.isDebugEnabled())
+                LOG.debug("Connected {} to {}", endPoint, address);
+
+            Connection connection = newConnection(endPoint, context);
+            endPoint.setConnection(connection);
+            endPoint.onOpen();
+            connection.onOpen();
+
+            @SuppressWarnings("unchecked")
+            Promise<Connection> promise = (Promise<Connection>)context.get(CONNECTION_PROMISE_CONTEXT_KEY);
+            if (promise != null)
+                promise.succeeded(connection);
+        }
+        catch (Throwable x)
+        {
+            connectFailed(x, context);
+        }
+    }
+}
@@ -0,0 +1,149 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.http2.server.HTTP2CServerConnectionFactory;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.server.AbstractConnector;
+import org.eclipse.jetty.server.ConnectionFactory;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.HttpConnectionFactory;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+
+/**
+ * <p>A server {@link org.eclipse.jetty.server.Connector} that accepts in-memory connections.</p>
+ * <p>Each call to {@link #connect(SocketAddress)} creates a pair of linked {@link StubEndPoint}s:
+ * the server side is handed to the default {@link ConnectionFactory} of this connector, while
+ * the client side is returned to the caller, typically a {@link StubClientConnector}.</p>
+ * <p>By default the connector speaks HTTP/1.1 and HTTP/2 with prior knowledge.</p>
+ */
+public class StubConnector extends AbstractConnector
+{
+    private final AtomicInteger _ports = new AtomicInteger(49152);
+    private Consumer<StubEndPoint> _endPointCustomizer;
+
+    public StubConnector(Server server)
+    {
+        this(server, new HttpConfiguration());
+    }
+
+    public StubConnector(Server server, HttpConfiguration httpConfiguration)
+    {
+        this(server, new HttpConnectionFactory(httpConfiguration), new HTTP2CServerConnectionFactory(httpConfiguration));
+    }
+
+    public StubConnector(Server server, ConnectionFactory... factories)
+    {
+        super(server, null, null, null, 0, factories);
+        setIdleTimeout(30000);
+    }
+
+    /**
+     * <p>Returns the {@link StubEndPoint} that carries the given request, so that
+     * a handler can inject faults in the response it is about to send.</p>
+     *
+     * @param request the request
+     * @return the server side endpoint, or null if the request was not received by a {@code StubConnector}
+     */
+    public static StubEndPoint getStubEndPoint(Request request)
+    {
+        EndPoint endPoint = request.getHttpChannel().getEndPoint();
+        return endPoint instanceof StubEndPoint ? (StubEndPoint)endPoint : null;
+    }
+
+    /**
+     * @return the customizer applied to the server side endpoint of new connections
+     */
+    public Consumer<StubEndPoint> getEndPointCustomizer()
+    {
+        return _endPointCustomizer;
+    }
+
+    /**
+     * <p>Sets a customizer invoked with the server side endpoint of each new connection,
+     * for example to inject a latency in all the responses.</p>
+     *
+     * @param endPointCustomizer the customizer applied to the server side endpoint of new connections
+     */
+    public void setEndPointCustomizer(Consumer<StubEndPoint> endPointCustomizer)
+    {
+        _endPointCustomizer = endPointCustomizer;
+    }
+
+    @Override
+    public Object getTransport()
+    {
+        return this;
+    }
+
+    /**
+     * <p>Opens a new in-memory connection to this connector.</p>
+     *
+     * @param address the address the client connects to, reported as the server local address
+     * @return the client side endpoint of the connection
+     */
+    public StubEndPoint connect(SocketAddress address)
+    {
+        if (!isStarted())
+            throw new IllegalStateException("!STARTED");
+        if (isShutdown())
+            throw new IllegalStateException("Shutdown");
+
+        SocketAddress clientAddress = new InetSocketAddress(InetAddress.getLoopbackAddress(), _ports.getAndIncrement());
+        StubEndPoint serverEndPoint = new StubEndPoint(getExecutor(), getScheduler(), getIdleTimeout(), address, clientAddress)
+        {
+            @Override
+            public void onClose(Throwable cause)
+            {
+                Connection connection = getConnection();
+                if (connection != null)
+                    connection.onClose(cause);
+                onEndPointClosed(this);
+                super.onClose(cause);
+            }
+        };
+        StubEndPoint clientEndPoint = new StubEndPoint(getExecutor(), getScheduler(), getIdleTimeout(), clientAddress, address);
+        StubEndPoint.link(serverEndPoint, clientEndPoint);
+
+        Consumer<StubEndPoint> customizer = getEndPointCustomizer();
+        if (customizer != null)
+            customizer.accept(serverEndPoint);
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("connecting {} to {}", clientEndPoint, serverEndPoint);
+
+        Connection connection = getDefaultConnectionFactory().newConnection(this, serverEndPoint);
+        serverEndPoint.setConnection(connection);
+        serverEndPoint.onOpen();
+        onEndPointOpened(serverEndPoint);
+        connection.onOpen();
+
+        return clientEndPoint;
+    }
+
+    @Override
+    protected void accept(int acceptorID)
+    {
+        // Connections are accepted by connect().
+        throw new UnsupportedOperationException();
+    }
+}
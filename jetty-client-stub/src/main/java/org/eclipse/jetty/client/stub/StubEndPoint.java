@@ -0,0 +1,386 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.io.IOException;
+import java.net.SocketAddress;
+import java.net.SocketException;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.concurrent.Executor;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.io.ByteArrayEndPoint;
+import org.eclipse.jetty.io.RuntimeIOException;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An in-memory {@link org.eclipse.jetty.io.EndPoint} that is one half of a
+ * connection created by {@link StubConnector}.</p>
+ * <p>The bytes flushed to this endpoint are copied and delivered to the
+ * {@link #getPeer() peer} endpoint as input, so that no socket is involved.</p>
+ * <p>Network faults can be injected on the bytes written by this endpoint:</p>
+ * <ul>
+ *   <li>{@link #setLatency(Duration) latency} delays each write before it reaches the peer</li>
+ *   <li>{@link #setThrottle(int, Duration) throttling} slices writes into chunks delivered at intervals</li>
+ *   <li>{@link #truncateAfter(long) truncation} closes the connection after a number of bytes</li>
+ *   <li>{@link #resetAfter(long) reset} aborts the connection after a number of bytes</li>
+ * </ul>
+ */
+public class StubEndPoint extends ByteArrayEndPoint
+{
+    private static final Logger LOG = LoggerFactory.getLogger(StubEndPoint.class);
+
+    private final AutoLock lock = new AutoLock();
+    private final Executor executor;
+    private final Scheduler scheduler;
+    private final SocketAddress localAddress;
+    private final SocketAddress remoteAddress;
+    private StubEndPoint peer;
+    private long latency;
+    private int throttleSize;
+    private long throttleInterval;
+    private long cutOff = -1;
+    private boolean cutOffReset;
+    private long lastDelivery;
+    private int pending;
+
+    public StubEndPoint(Executor executor, Scheduler scheduler, long idleTimeout, SocketAddress localAddress, SocketAddress remoteAddress)
+    {
+        super(scheduler, idleTimeout);
+        this.executor = executor;
+        this.scheduler = scheduler;
+        this.localAddress = localAddress;
+        this.remoteAddress = remoteAddress;
+        this.lastDelivery = System.nanoTime();
+    }
+
+    /**
+     * <p>Links the two given endpoints, so that the bytes written by one are read by the other.</p>
+     *
+     * @param endPoint1 one endpoint
+     * @param endPoint2 the other endpoint
+     */
+    public static void link(StubEndPoint endPoint1, StubEndPoint endPoint2)
+    {
+        endPoint1.peer = endPoint2;
+        endPoint2.peer = endPoint1;
+    }
+
+    /**
+     * @return the endpoint that reads the bytes written by this endpoint
+     */
+    public StubEndPoint getPeer()
+    {
+        return peer;
+    }
+
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return localAddress;
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return remoteAddress;
+    }
+
+    /**
+     * @return the delay applied to each write before it reaches the peer
+     */
+    public Duration getLatency()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return Duration.ofNanos(latency);
+        }
+    }
+
+    /**
+     * @param latency the delay applied to each write before it reaches the peer
+     */
+    public void setLatency(Duration latency)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            this.latency = latency.toNanos();
+        }
+    }
+
+    /**
+     * <p>Slows down the bytes written by this endpoint, delivering them
+     * to the peer in chunks of {@code chunkSize} bytes every {@code interval}.</p>
+     *
+     * @param chunkSize the max number of bytes delivered at once, or 0 to disable throttling
+     * @param interval the time between two chunks
+     */
+    public void setThrottle(int chunkSize, Duration interval)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            this.throttleSize = Math.max(0, chunkSize);
+            this.throttleInterval = interval.toNanos();
+        }
+    }
+
+    /**
+     * <p>Closes the connection after {@code bytes} more bytes have been written, as if the
+     * other side of the network went away; the peer reads the bytes and then EOF.</p>
+     *
+     * @param bytes the number of bytes that are still delivered to the peer
+     */
+    public void truncateAfter(long bytes)
+    {
+        cutOff(bytes, false);
+    }
+
+    /**
+     * <p>Aborts the connection after {@code bytes} more bytes have been written;
+     * the peer reads the bytes and then fails with a "Connection reset" error.</p>
+     *
+     * @param bytes the number of bytes that are still delivered to the peer
+     */
+    public void resetAfter(long bytes)
+    {
+        cutOff(bytes, true);
+    }
+
+    private void cutOff(long bytes, boolean reset)
+    {
+        if (bytes < 0)
+            throw new IllegalArgumentException("Invalid byte count " + bytes);
+        try (AutoLock l = lock.lock())
+        {
+            this.cutOff = bytes;
+            this.cutOffReset = reset;
+        }
+        if (bytes == 0)
+            afterPending(() -> cut(reset));
+    }
+
+    /**
+     * <p>Aborts the connection immediately, discarding the bytes not yet delivered.</p>
+     */
+    public void reset()
+    {
+        SocketException failure = new SocketException("Connection reset");
+        close(failure);
+        peer.close(failure);
+    }
+
+    /**
+     * <p>Removes the latency, throttling, truncation and reset configured on this endpoint.</p>
+     */
+    public void clearFaults()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            latency = 0;
+            throttleSize = 0;
+            throttleInterval = 0;
+            cutOff = -1;
+            cutOffReset = false;
+        }
+    }
+
+    @Override
+    protected void execute(Runnable task)
+    {
+        executor.execute(task);
+    }
+
+    @Override
+    public boolean flush(ByteBuffer... buffers) throws IOException
+    {
+        if (!isOpen())
+            throw new IOException("CLOSED");
+        if (isOutputShutdown())
+            throw new IOException("OSHUT");
+
+        boolean cut = false;
+        boolean reset = false;
+        boolean written = false;
+        try (AutoLock l = lock.lock())
+        {
+            for (ByteBuffer buffer : buffers)
+            {
+                if (cut)
+                {
+                    // Bytes after the cut off are silently lost.
+                    buffer.position(buffer.limit());
+                    continue;
+                }
+
+                int length = buffer.remaining();
+                if (length == 0)
+                    continue;
+                if (cutOff >= 0 && length > cutOff)
+                    length = (int)cutOff;
+
+                ByteBuffer copy = ByteBuffer.allocate(length);
+                int limit = buffer.limit();
+                buffer.limit(buffer.position() + length);
+                copy.put(buffer).flip();
+                buffer.limit(limit);
+                if (length > 0)
+                {
+                    written = true;
+                    deliver(copy);
+                }
+
+                if (cutOff >= 0)
+                {
+                    cutOff -= length;
+                    if (cutOff == 0)
+                    {
+                        cut = true;
+                        reset = cutOffReset;
+                        cutOff = -1;
+                        buffer.position(limit);
+                    }
+                }
+            }
+        }
+
+        if (written)
+            notIdle();
+        if (cut)
+        {
+            boolean abort = reset;
+            afterPending(() -> cut(abort));
+        }
+        return true;
+    }
+
+    private void deliver(ByteBuffer buffer)
+    {
+        assert lock.isHeldByCurrentThread();
+        while (buffer.hasRemaining())
+        {
+            ByteBuffer chunk = buffer;
+            if (throttleSize > 0 && buffer.remaining() > throttleSize)
+            {
+                chunk = buffer.slice();
+                chunk.limit(throttleSize);
+                buffer.position(buffer.position() + throttleSize);
+            }
+            else
+            {
+                buffer = BufferUtil.EMPTY_BUFFER;
+            }
+
+            long interval = throttleSize > 0 ? throttleInterval : 0;
+            if (pending == 0 && latency == 0 && interval == 0)
+            {
+                receive(chunk);
+            }
+            else
+            {
+                long now = System.nanoTime();
+                long deliveryTime = Math.max(now + latency, lastDelivery + interval);
+                lastDelivery = deliveryTime;
+                ++pending;
+                ByteBuffer content = chunk;
+                scheduler.schedule(() -> delivered(content), Math.max(0, deliveryTime - now), TimeUnit.NANOSECONDS);
+            }
+        }
+    }
+
+    private void delivered(ByteBuffer buffer)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            --pending;
+        }
+        receive(buffer);
+    }
+
+    private void afterPending(Runnable task)
+    {
+        long delay;
+        try (AutoLock l = lock.lock())
+        {
+            if (pending == 0)
+            {
+                delay = -1;
+            }
+            else
+            {
+                // Runs after the pending deliveries, since the scheduler runs tasks in order.
+                delay = Math.max(0, lastDelivery - System.nanoTime());
+                ++pending;
+            }
+        }
+        if (delay < 0)
+        {
+            task.run();
+        }
+        else
+        {
+            scheduler.schedule(() ->
+            {
+                try (AutoLock l = lock.lock())
+                {
+                    --pending;
+                }
+                task.run();
+            }, delay, TimeUnit.NANOSECONDS);
+        }
+    }
+
+    private void receive(ByteBuffer buffer)
+    {
+        try
+        {
+            if (peer.isOpen())
+                peer.addInputAndExecute(buffer);
+        }
+        catch (RuntimeIOException x)
+        {
+            // The peer has already seen EOF.
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not deliver {} to {}", BufferUtil.toDetailString(buffer), peer, x);
+        }
+    }
+
+    private void cut(boolean reset)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} {}", reset ? "Resetting" : "Truncating", this);
+        if (reset)
+            reset();
+        else
+            close();
+    }
+
+    @Override
+    public void doShutdownOutput()
+    {
+        super.doShutdownOutput();
+        afterPending(() -> receive(null));
+    }
+
+    @Override
+    public void doClose()
+    {
+        super.doClose();
+        afterPending(() -> receive(null));
+    }
+}
@@ -0,0 +1,338 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.pathmap.PathSpec;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.AbstractHandlerContainer;
+
+/**
+ * <p>A handler that dispatches the requests to scripted {@link Stub}s.</p>
+ * <p>A stub matches requests by method and {@link PathSpec path spec}, in the order
+ * the stubs were added, and either sends a canned response or delegates to a
+ * {@link Handler}; requests that match no stub are left unhandled.</p>
+ * <p>When the request is received through a {@link StubConnector}, a stub may also
+ * delay, slow down, truncate or reset its response; see {@link StubEndPoint}.
+ * These faults act on the whole connection, so with HTTP/2 they also affect
+ * the other streams of the connection.</p>
+ */
+public class StubHandler extends AbstractHandlerContainer
+{
+    private final List<Stub> _stubs = new CopyOnWriteArrayList<>();
+
+    /**
+     * <p>Adds a stub matching requests with any method.</p>
+     *
+     * @param pathSpec the path spec of the requests
+     * @return the new stub
+     */
+    public Stub when(String pathSpec)
+    {
+        return when(null, pathSpec);
+    }
+
+    /**
+     * <p>Adds a stub matching requests with the given method.</p>
+     *
+     * @param method the method of the requests, or null to match any method
+     * @param pathSpec the path spec of the requests
+     * @return the new stub
+     */
+    public Stub when(String method, String pathSpec)
+    {
+        Stub stub = new Stub(method, PathSpec.from(pathSpec));
+        _stubs.add(stub);
+        return stub;
+    }
+
+    /**
+     * @return the stubs, in matching order
+     */
+    public List<Stub> getStubs()
+    {
+        return List.copyOf(_stubs);
+    }
+
+    /**
+     * <p>Removes all the stubs.</p>
+     */
+    public void clear()
+    {
+        for (Stub stub : _stubs)
+        {
+            if (stub._handler != null)
+                removeBean(stub._handler);
+        }
+        _stubs.clear();
+    }
+
+    @Override
+    public Handler[] getHandlers()
+    {
+        List<Handler> handlers = new ArrayList<>();
+        for (Stub stub : _stubs)
+        {
+            if (stub._handler != null)
+                handlers.add(stub._handler);
+        }
+        return handlers.toArray(new Handler[0]);
+    }
+
+    @Override
+    protected void expandChildren(List<Handler> list, Class<?> byClass)
+    {
+        for (Handler handler : getHandlers())
+        {
+            expandHandler(handler, list, byClass);
+        }
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (!isStarted())
+            return;
+
+        // Faults injected by the previous exchange on this connection do not apply to this one.
+        StubEndPoint endPoint = StubConnector.getStubEndPoint(baseRequest);
+        if (endPoint != null)
+            endPoint.clearFaults();
+
+        for (Stub stub : _stubs)
+        {
+            if (stub.matches(baseRequest.getMethod(), target))
+            {
+                stub.handle(endPoint, target, baseRequest, request, response);
+                return;
+            }
+        }
+    }
+
+    /**
+     * <p>A scripted response to the requests matching a method and a path spec.</p>
+     * <p>The configuration methods return this stub, so that they can be chained.</p>
+     */
+    public class Stub
+    {
+        private final AtomicInteger _hits = new AtomicInteger();
+        private final String _method;
+        private final PathSpec _pathSpec;
+        private final HttpFields.Mutable _headers = HttpFields.build();
+        private int _status = HttpServletResponse.SC_OK;
+        private byte[] _content;
+        private Handler _handler;
+        private Duration _delay = Duration.ZERO;
+        private int _throttleSize;
+        private Duration _throttleInterval = Duration.ZERO;
+        private long _truncateAfter = -1;
+        private long _resetAfter = -1;
+
+        private Stub(String method, PathSpec pathSpec)
+        {
+            _method = method;
+            _pathSpec = pathSpec;
+        }
+
+        /**
+         * <p>Responds with the given status and no content.</p>
+         *
+         * @param status the response status
+         * @return this stub
+         */
+        public Stub respond(int status)
+        {
+            _status = status;
+            return this;
+        }
+
+        /**
+         * <p>Responds with the given status and content.</p>
+         *
+         * @param status the response status
+         * @param contentType the response content type
+         * @param content the response content, encoded in UTF-8
+         * @return this stub
+         */
+        public Stub respond(int status, String contentType, String content)
+        {
+            _status = status;
+            _headers.put(HttpHeader.CONTENT_TYPE, contentType);
+            _content = content.getBytes(StandardCharsets.UTF_8);
+            return this;
+        }
+
+        /**
+         * <p>Adds a header to the response.</p>
+         *
+         * @param name the header name
+         * @param value the header value
+         * @return this stub
+         */
+        public Stub header(String name, String value)
+        {
+            _headers.add(name, value);
+            return this;
+        }
+
+        /**
+         * <p>Delegates the requests to the given handler instead of sending a canned response.</p>
+         *
+         * @param handler the handler of the requests
+         * @return this stub
+         */
+        public Stub handler(Handler handler)
+        {
+            if (_handler != null)
+                removeBean(_handler);
+            _handler = handler;
+            handler.setServer(getServer());
+            addBean(handler, true);
+            return this;
+        }
+
+        /**
+         * @param delay the delay before the response reaches the client
+         * @return this stub
+         * @see StubEndPoint#setLatency(Duration)
+         */
+        public Stub delay(Duration delay)
+        {
+            _delay = delay;
+            return this;
+        }
+
+        /**
+         * @param chunkSize the max number of response bytes delivered at once
+         * @param interval the time between two chunks
+         * @return this stub
+         * @see StubEndPoint#setThrottle(int, Duration)
+         */
+        public Stub throttle(int chunkSize, Duration interval)
+        {
+            _throttleSize = chunkSize;
+            _throttleInterval = interval;
+            return this;
+        }
+
+        /**
+         * @param bytes the number of response bytes delivered before the connection is closed
+         * @return this stub
+         * @see StubEndPoint#truncateAfter(long)
+         */
+        public Stub truncateAfter(long bytes)
+        {
+            _truncateAfter = bytes;
+            _resetAfter = -1;
+            return this;
+        }
+
+        /**
+         * @param bytes the number of response bytes delivered before the connection is reset
+         * @return this stub
+         * @see StubEndPoint#resetAfter(long)
+         */
+        public Stub resetAfter(long bytes)
+        {
+            _resetAfter = bytes;
+            _truncateAfter = -1;
+            return this;
+        }
+
+        /**
+         * <p>Resets the connection instead of responding.</p>
+         *
+         * @return this stub
+         */
+        public Stub reset()
+        {
+            return resetAfter(0);
+        }
+
+        /**
+         * @return the number of requests that matched this stub
+         */
+        public int getHits()
+        {
+            return _hits.get();
+        }
+
+        private boolean matches(String method, String path)
+        {
+            if (_method != null && !_method.equalsIgnoreCase(method))
+                return false;
+            return _pathSpec.matches(path);
+        }
+
+        private void handle(StubEndPoint endPoint, String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+        {
+            _hits.incrementAndGet();
+
+            if (endPoint != null)
+            {
+                endPoint.setLatency(_delay);
+                endPoint.setThrottle(_throttleSize, _throttleInterval);
+                if (_truncateAfter >= 0)
+                    endPoint.truncateAfter(_truncateAfter);
+                else if (_resetAfter >= 0)
+                    endPoint.resetAfter(_resetAfter);
+            }
+
+            if (_resetAfter == 0)
+            {
+                // The connection is already gone, there is nothing to respond.
+                baseRequest.setHandled(true);
+                return;
+            }
+
+            if (_handler != null)
+            {
+                _handler.handle(target, baseRequest, request, response);
+                return;
+            }
+
+            baseRequest.setHandled(true);
+            response.setStatus(_status);
+            for (HttpField field : _headers)
+            {
+                response.addHeader(field.getName(), field.getValue());
+            }
+            if (_content != null)
+            {
+                response.setContentLength(_content.length);
+                response.getOutputStream().write(_content);
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s %s,hits=%d}", getClass().getSimpleName(), hashCode(), _method == null ? "*" : _method, _pathSpec.getDeclaration(), getHits());
+        }
+    }
+}
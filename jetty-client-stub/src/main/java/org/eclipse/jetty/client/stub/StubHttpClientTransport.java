@@ -0,0 +1,114 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.net.InetSocketAddress;
+import java.util.List;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.dynamic.HttpClientTransportDynamic;
+import org.eclipse.jetty.client.http.HttpClientConnectionFactory;
+import org.eclipse.jetty.http2.client.HTTP2Client;
+import org.eclipse.jetty.http2.client.http.ClientConnectionFactoryOverHTTP2;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+
+/**
+ * <p>An {@link org.eclipse.jetty.client.HttpClientTransport} that routes the requests to
+ * server {@link Handler}s in the same JVM, through in-memory connections, so that client
+ * code can be tested without starting a real server.</p>
+ * <p>The transport speaks HTTP/1.1 and, for requests with version {@code HTTP/2},
+ * HTTP/2 with prior knowledge; the request URI host is never resolved, so any
+ * host name can be used.</p>
+ * <pre>
+ * StubHandler stubs = new StubHandler();
+ * stubs.when("GET", "/users/*").respond(200, "application/json", "[]");
+ * HttpClient httpClient = new HttpClient(new StubHttpClientTransport(stubs));
+ * httpClient.start();
+ * ContentResponse response = httpClient.GET("http://api.example.com/users/1");
+ * </pre>
+ *
+ * @see StubHandler
+ * @see StubEndPoint
+ */
+public class StubHttpClientTransport extends HttpClientTransportDynamic
+{
+    private final StubConnector connector;
+
+    /**
+     * <p>Creates a transport that routes the requests to the given handler,
+     * hosted by a {@link Server} whose lifecycle is managed by this transport.</p>
+     *
+     * @param handler the handler of the requests
+     */
+    public StubHttpClientTransport(Handler handler)
+    {
+        this(newStubConnector(handler));
+        addBean(connector.getServer(), true);
+    }
+
+    /**
+     * <p>Creates a transport that connects to the given connector; the lifecycle
+     * of the connector {@link Server} is not managed by this transport.</p>
+     *
+     * @param connector the connector to connect to
+     */
+    public StubHttpClientTransport(StubConnector connector)
+    {
+        this(new StubClientConnector(connector));
+    }
+
+    private StubHttpClientTransport(StubClientConnector clientConnector)
+    {
+        super(clientConnector, HttpClientConnectionFactory.HTTP11, new ClientConnectionFactoryOverHTTP2.HTTP2(new HTTP2Client(clientConnector)));
+        this.connector = clientConnector.getStubConnector();
+    }
+
+    private static StubConnector newStubConnector(Handler handler)
+    {
+        Server server = new Server();
+        StubConnector connector = new StubConnector(server);
+        server.addConnector(connector);
+        server.setHandler(handler);
+        return connector;
+    }
+
+    /**
+     * @return the server connector the requests are sent to
+     */
+    public StubConnector getStubConnector()
+    {
+        return connector;
+    }
+
+    @Override
+    public void setHttpClient(HttpClient client)
+    {
+        super.setHttpClient(client);
+        // Stub hosts need not exist, so skip the DNS lookup.
+        if (!(client.getSocketAddressResolver() instanceof UnresolvedAddressResolver))
+            client.setSocketAddressResolver(new UnresolvedAddressResolver());
+    }
+
+    private static class UnresolvedAddressResolver implements SocketAddressResolver
+    {
+        @Override
+        public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+        {
+            promise.succeeded(List.of(InetSocketAddress.createUnresolved(host, port)));
+        }
+    }
+}
@@ -0,0 +1,153 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.stub;
+
+import java.io.IOException;
+import java.time.Duration;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class StubHttpClientTransportTest
+{
+    private StubHandler stubs;
+    private HttpClient client;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        stubs = new StubHandler();
+        client = new HttpClient(new StubHttpClientTransport(stubs));
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        client.stop();
+    }
+
+    private ContentResponse send(String path, HttpVersion version) throws Exception
+    {
+        return client.newRequest("http://api.example.com" + path)
+            .version(version)
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+    }
+
+    @Test
+    public void testCannedResponseOverHTTP11AndHTTP2() throws Exception
+    {
+        StubHandler.Stub stub = stubs.when("GET", "/users/*").respond(HttpStatus.OK_200, "application/json", "[]").header("X-Stub", "yes");
+
+        for (HttpVersion version : new HttpVersion[]{HttpVersion.HTTP_1_1, HttpVersion.HTTP_2})
+        {
+            ContentResponse response = send("/users/1", version);
+            assertThat(response.getVersion(), is(version));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.getMediaType(), is("application/json"));
+            assertThat(response.getHeaders().get("X-Stub"), is("yes"));
+            assertThat(response.getContentAsString(), is("[]"));
+        }
+        assertThat(stub.getHits(), is(2));
+
+        // The method must match, and unmatched requests are not handled.
+        assertThat(client.POST("http://api.example.com/users/1").timeout(5, TimeUnit.SECONDS).send().getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(send("/other", HttpVersion.HTTP_1_1).getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testDelegateToHandler() throws Exception
+    {
+        stubs.when("/echo/*").handler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                response.getWriter().print(request.getMethod() + " " + request.getServerName() + target);
+            }
+        });
+
+        assertThat(send("/echo/x", HttpVersion.HTTP_1_1).getContentAsString(), is("GET api.example.com/echo/x"));
+        assertThat(send("/echo/y", HttpVersion.HTTP_2).getContentAsString(), is("GET api.example.com/echo/y"));
+    }
+
+    @Test
+    public void testDelay() throws Exception
+    {
+        stubs.when("/slow").respond(HttpStatus.OK_200, "text/plain", "late").delay(Duration.ofMillis(500));
+        stubs.when("/fast").respond(HttpStatus.OK_200, "text/plain", "early");
+
+        long begin = System.nanoTime();
+        assertThat(send("/slow", HttpVersion.HTTP_1_1).getContentAsString(), is("late"));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin), greaterThanOrEqualTo(500L));
+
+        // The delay does not apply to the next exchange on the same connection.
+        begin = System.nanoTime();
+        assertThat(send("/fast", HttpVersion.HTTP_1_1).getContentAsString(), is("early"));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin) < 500L, is(true));
+    }
+
+    @Test
+    public void testSlowBody() throws Exception
+    {
+        String content = "x".repeat(1000);
+        stubs.when("/slow").respond(HttpStatus.OK_200, "text/plain", content).throttle(250, Duration.ofMillis(100));
+
+        long begin = System.nanoTime();
+        assertThat(send("/slow", HttpVersion.HTTP_1_1).getContentAsString(), is(content));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin), greaterThanOrEqualTo(400L));
+    }
+
+    @Test
+    public void testTruncatedResponse() throws Exception
+    {
+        stubs.when("/truncated").respond(HttpStatus.OK_200, "text/plain", "x".repeat(1000)).truncateAfter(200);
+
+        assertThrows(ExecutionException.class, () -> send("/truncated", HttpVersion.HTTP_1_1));
+        assertThrows(ExecutionException.class, () -> send("/truncated", HttpVersion.HTTP_2));
+    }
+
+    @Test
+    public void testConnectionReset() throws Exception
+    {
+        stubs.when("/reset").reset();
+        stubs.when("/ok").respond(HttpStatus.NO_CONTENT_204);
+
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> send("/reset", HttpVersion.HTTP_1_1));
+        assertThat(failure.getCause().getMessage(), containsString("Connection reset"));
+
+        // A new connection is opened for the next request.
+        assertThat(send("/ok", HttpVersion.HTTP_1_1).getStatus(), is(HttpStatus.NO_CONTENT_204));
+    }
+}
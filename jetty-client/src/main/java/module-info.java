@@ -23,9 +23,12 @@ module org.eclipse.jetty.client
     // Only required if using SPNEGO.
     requires static java.security.jgss;
     requires static org.eclipse.jetty.jmx;
+    // Only required if using OAuth2 or HAR recording.
+    requires static org.eclipse.jetty.util.ajax;
 
     exports org.eclipse.jetty.client;
     exports org.eclipse.jetty.client.api;
+    exports org.eclipse.jetty.client.cache;
     exports org.eclipse.jetty.client.dynamic;
     exports org.eclipse.jetty.client.http;
     exports org.eclipse.jetty.client.util;
@@ -17,12 +17,15 @@ import java.io.IOException;
 import java.util.ArrayDeque;
 import java.util.ArrayList;
 import java.util.Collection;
+import java.util.EventListener;
 import java.util.List;
 import java.util.Objects;
 import java.util.Queue;
 import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
+import java.util.function.Predicate;
 import java.util.stream.Collectors;
 
 import org.eclipse.jetty.client.api.Connection;
@@ -47,12 +50,14 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
 {
     private static final Logger LOG = LoggerFactory.getLogger(AbstractConnectionPool.class);
 
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
     private final AtomicInteger pending = new AtomicInteger();
     private final HttpDestination destination;
     private final Callback requester;
     private final Pool<Connection> pool;
     private boolean maximizeConnections;
     private volatile long maxDurationNanos = 0L;
+    private volatile long replacementMarginNanos = 0L;
 
     protected AbstractConnectionPool(HttpDestination destination, int maxConnections, boolean cache, Callback requester)
     {
@@ -122,6 +127,25 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
         this.maxDurationNanos = TimeUnit.MILLISECONDS.toNanos(maxDurationInMs);
     }
 
+    /**
+     * <p>Get the time in milliseconds before the {@link #getMaxDuration() max duration}
+     * at which the pool's connections are replaced.</p>
+     * <p>A connection that is released within this time from its expiration is closed,
+     * and a new connection is created in its place, so that the next request does not
+     * have to wait for the new connection to be opened.
+     * Values {@code 0} and negative mean that connections are not replaced.</p>
+     */
+    @ManagedAttribute(value = "The time in milliseconds before the max duration at which a connection gets replaced")
+    public long getReplacementMargin()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(replacementMarginNanos);
+    }
+
+    public void setReplacementMargin(long replacementMarginInMs)
+    {
+        this.replacementMarginNanos = TimeUnit.MILLISECONDS.toNanos(replacementMarginInMs);
+    }
+
     protected int getMaxMultiplex()
     {
         return pool.getMaxMultiplex();
@@ -201,6 +225,31 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
         this.maximizeConnections = maximizeConnections;
     }
 
+    /**
+     * @param listener the listener to notify of the decisions of this pool
+     */
+    public void addListener(Listener listener)
+    {
+        listeners.add(Objects.requireNonNull(listener));
+    }
+
+    /**
+     * @param listener the listener to remove
+     * @return whether the listener was removed
+     */
+    public boolean removeListener(Listener listener)
+    {
+        return listeners.remove(listener);
+    }
+
+    /**
+     * @return the listeners of this pool
+     */
+    protected List<Listener> getListeners()
+    {
+        return listeners;
+    }
+
     /**
      * <p>Returns an idle connection, if available;
      * if an idle connection is not available, and the given {@code create} parameter is {@code true}
@@ -227,6 +276,8 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
         {
             tryCreate(create);
             connection = activate();
+            if (connection == null)
+                notifyUnavailable(create);
         }
         return connection;
     }
@@ -282,6 +333,7 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
 
         if (LOG.isDebugEnabled())
             LOG.debug("Creating connection {}/{} at {}", connectionCount, getMaxConnectionCount(), entry);
+        notifyCreating();
         Promise<Connection> future = new FutureConnection(entry);
         destination.newConnection(future);
     }
@@ -313,15 +365,25 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
     {
         while (true)
         {
-            Pool<Connection>.Entry entry = pool.acquire();
+            Pool<Connection>.Entry entry = acquireEntry(pool);
             if (entry != null)
             {
                 Connection connection = entry.getPooled();
 
+                EntryHolder holder = (EntryHolder)((Attachable)connection).getAttachment();
+                if (holder != null && holder.draining)
+                {
+                    boolean canClose = remove(connection);
+                    if (canClose)
+                        IO.close(connection);
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Connection removed{} due to draining {} {}", (canClose ? " and closed" : ""), entry, pool);
+                    continue;
+                }
+
                 long maxDurationNanos = this.maxDurationNanos;
                 if (maxDurationNanos > 0L)
                 {
-                    EntryHolder holder = (EntryHolder)((Attachable)connection).getAttachment();
                     if (holder.isExpired(maxDurationNanos))
                     {
                         boolean canClose = remove(connection);
@@ -329,19 +391,38 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
                             IO.close(connection);
                         if (LOG.isDebugEnabled())
                             LOG.debug("Connection removed{} due to expiration {} {}", (canClose ? " and closed" : ""), entry, pool);
+                        notifyExpired(connection);
                         continue;
                     }
                 }
 
+                if (holder != null)
+                    holder.lastUsedNanoTime = NanoTime.now();
                 if (LOG.isDebugEnabled())
                     LOG.debug("Activated {} {}", entry, pool);
                 acquired(connection);
+                notifyAcquired(connection);
                 return connection;
             }
             return null;
         }
     }
 
+    /**
+     * <p>Acquires an entry from the given pool.</p>
+     * <p>The default implementation selects the entry using the
+     * {@link Pool.StrategyType strategy} of the pool, while subclasses may
+     * select a specific entry among the {@link Pool#values() pool entries}
+     * and then {@link Pool#acquire(Pool.Entry) acquire} it.</p>
+     *
+     * @param pool the pool to acquire the entry from
+     * @return the acquired entry, or {@code null} if no entry could be acquired
+     */
+    protected Pool<Connection>.Entry acquireEntry(Pool<Connection> pool)
+    {
+        return pool.acquire();
+    }
+
     @Override
     public boolean isActive(Connection connection)
     {
@@ -373,13 +454,24 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
             return true;
 
         long maxDurationNanos = this.maxDurationNanos;
-        if (maxDurationNanos > 0L && holder.isExpired(maxDurationNanos))
+        if (holder.draining || (maxDurationNanos > 0L && holder.isExpired(maxDurationNanos)))
         {
-            // Remove instead of release if the connection expired.
+            // Remove instead of release if the connection is draining or expired.
             return !remove(connection);
         }
+        else if (isReplaceable(holder, maxDurationNanos))
+        {
+            // Replace the connection before it expires.
+            boolean removed = remove(connection);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Replacing ({}) {} {}", removed, holder.entry, pool);
+            if (removed && !isClosed())
+                preCreateConnections(1);
+            return !removed;
+        }
         else
         {
+            holder.lastUsedNanoTime = NanoTime.now();
             // Release if the connection has not expired, then remove if not reusable.
             boolean reusable = pool.release(holder.entry);
             if (LOG.isDebugEnabled())
@@ -390,6 +482,14 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
         }
     }
 
+    private boolean isReplaceable(EntryHolder holder, long maxDurationNanos)
+    {
+        long replacementMarginNanos = this.replacementMarginNanos;
+        if (replacementMarginNanos <= 0L || replacementMarginNanos >= maxDurationNanos)
+            return false;
+        return holder.isExpired(maxDurationNanos - replacementMarginNanos);
+    }
+
     @Override
     public boolean remove(Connection connection)
     {
@@ -412,6 +512,88 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
         return removed;
     }
 
+    /**
+     * <p>Drains the connections that match the given predicate.</p>
+     * <p>Draining connections are not used for new exchanges: idle connections
+     * are removed from this pool and closed immediately, while connections that
+     * are in use are removed and closed when they are released.</p>
+     *
+     * @param predicate the predicate selecting the connections to drain
+     * @return the number of connections that have been drained
+     */
+    public int drain(Predicate<Connection> predicate)
+    {
+        int drained = 0;
+        for (Pool<Connection>.Entry entry : pool.values())
+        {
+            Connection connection = entry.getPooled();
+            if (connection == null || entry.isClosed() || !predicate.test(connection))
+                continue;
+            EntryHolder holder = (EntryHolder)((Attachable)connection).getAttachment();
+            if (holder == null || holder.draining)
+                continue;
+            holder.draining = true;
+            ++drained;
+            // Take the connection out of the pool if nobody is using it,
+            // otherwise it is removed by the last release.
+            if (pool.acquire(entry))
+            {
+                if (entry.getMultiplexCount() == 1)
+                {
+                    if (remove(connection))
+                        IO.close(connection);
+                }
+                else
+                {
+                    pool.release(entry);
+                }
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Draining {} {}", entry, pool);
+        }
+        return drained;
+    }
+
+    /**
+     * <p>Quarantines the given connection, so that it is not used for new exchanges
+     * and it is closed as soon as the exchanges in progress on it complete.</p>
+     *
+     * @param connection the connection to quarantine
+     * @return whether the connection has been quarantined
+     * @see #drain(Predicate)
+     */
+    public boolean quarantine(Connection connection)
+    {
+        return drain(pooled -> pooled == connection) > 0;
+    }
+
+    /**
+     * <p>Returns a snapshot of the connections of this pool,
+     * typically used to troubleshoot the exhaustion of the pool.</p>
+     *
+     * @return the connections of this pool with their age, idle time and exchanges in progress
+     * @see ConnectionPoolInspector
+     */
+    public List<ConnectionPoolInspector.ConnectionInfo> getConnectionInfos()
+    {
+        List<ConnectionPoolInspector.ConnectionInfo> infos = new ArrayList<>();
+        for (Pool<Connection>.Entry entry : pool.values())
+        {
+            Connection connection = entry.getPooled();
+            if (connection == null || entry.isClosed())
+                continue;
+            EntryHolder holder = (EntryHolder)((Attachable)connection).getAttachment();
+            if (holder == null)
+                continue;
+            int active = entry.getMultiplexCount();
+            int maxMultiplex = connection instanceof Multiplexable ? ((Multiplexable)connection).getMaxMultiplex() : getMaxMultiplex();
+            long age = NanoTime.millisSince(holder.creationNanoTime);
+            long idle = active == 0 ? NanoTime.millisSince(holder.lastUsedNanoTime) : 0L;
+            infos.add(new ConnectionPoolInspector.ConnectionInfo(this, destination.asString(), connection, age, idle, active, maxMultiplex, holder.draining));
+        }
+        return infos;
+    }
+
     @Deprecated
     protected boolean remove(Connection connection, boolean force)
     {
@@ -439,6 +621,66 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
     {
     }
 
+    private void notifyCreating()
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onCreating(this);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyAcquired(Connection connection)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onAcquired(this, connection);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyUnavailable(boolean create)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onUnavailable(this, create);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyExpired(Connection connection)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onExpired(this, connection);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
     Queue<Connection> getIdleConnections()
     {
         return pool.values().stream()
@@ -531,6 +773,56 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
             pool);
     }
 
+    /**
+     * <p>A listener of the decisions of an {@link AbstractConnectionPool},
+     * typically used to debug how connections are opened and selected.</p>
+     * <p>Listeners are notified synchronously by the thread that acquires
+     * connections, so their implementation must be fast and non-blocking.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when the pool decides to open a new connection.</p>
+         *
+         * @param pool the pool
+         */
+        public default void onCreating(AbstractConnectionPool pool)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the pool selects a connection to send a request.</p>
+         *
+         * @param pool the pool
+         * @param connection the connection selected
+         */
+        public default void onAcquired(AbstractConnectionPool pool, Connection connection)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the pool could not provide a connection,
+         * so that the request remains queued until a connection is available.</p>
+         *
+         * @param pool the pool
+         * @param create whether the opening of a new connection was requested
+         */
+        public default void onUnavailable(AbstractConnectionPool pool, boolean create)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the pool discards a connection
+         * because it exceeded the {@link AbstractConnectionPool#getMaxDuration() max duration}.</p>
+         *
+         * @param pool the pool
+         * @param connection the connection discarded
+         */
+        public default void onExpired(AbstractConnectionPool pool, Connection connection)
+        {
+        }
+    }
+
     private class FutureConnection extends Promise.Completable<Connection>
     {
         private final Pool<Connection>.Entry reserved;
@@ -579,6 +871,8 @@ public abstract class AbstractConnectionPool extends ContainerLifeCycle implemen
     {
         private final Pool<Connection>.Entry entry;
         private final long creationNanoTime = NanoTime.now();
+        private volatile long lastUsedNanoTime = creationNanoTime;
+        private volatile boolean draining;
 
         private EntryHolder(Pool<Connection>.Entry entry)
         {
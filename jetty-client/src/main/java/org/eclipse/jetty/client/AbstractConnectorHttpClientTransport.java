@@ -13,22 +13,35 @@
 
 package org.eclipse.jetty.client;
 
+import java.net.Inet6Address;
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
 import java.time.Duration;
+import java.util.ArrayList;
+import java.util.List;
 import java.util.Map;
 import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
 
 import org.eclipse.jetty.client.api.Connection;
 import org.eclipse.jetty.io.ClientConnector;
 import org.eclipse.jetty.util.Promise;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
 
 @ManagedObject
 public abstract class AbstractConnectorHttpClientTransport extends AbstractHttpClientTransport
 {
+    private static final Logger LOG = LoggerFactory.getLogger(AbstractConnectorHttpClientTransport.class);
+
     private final ClientConnector connector;
+    private boolean happyEyeballs;
+    private Duration connectionAttemptDelay = Duration.ofMillis(250);
 
     protected AbstractConnectorHttpClientTransport(ClientConnector connector)
     {
@@ -47,6 +60,56 @@ public abstract class AbstractConnectorHttpClientTransport extends AbstractHttpC
         return connector.getSelectors();
     }
 
+    /**
+     * @return whether connection attempts to hosts with multiple addresses are raced
+     * @see #setHappyEyeballs(boolean)
+     */
+    @ManagedAttribute("Whether connection attempts to hosts with multiple addresses are raced")
+    public boolean isHappyEyeballs()
+    {
+        return happyEyeballs;
+    }
+
+    /**
+     * <p>Sets whether connection attempts to hosts that resolve to multiple addresses
+     * are raced, as defined by <a href="https://datatracker.ietf.org/doc/html/rfc8305">RFC 8305</a>
+     * (Happy Eyeballs version 2).</p>
+     * <p>When enabled, the addresses are sorted alternating IPv6 and IPv4 addresses,
+     * starting with the family of the first address returned by the resolver;
+     * a connection attempt to the next address is started when the previous attempt
+     * fails, or after the {@link #setConnectionAttemptDelay(Duration) connection attempt delay},
+     * whichever comes first.
+     * The first connection that is established is used, the others are closed.</p>
+     * <p>When disabled, the addresses are tried sequentially, each attempt waiting for
+     * the previous to fail.</p>
+     * <p>The address of the connection that won the race is available from the requests
+     * sent over that connection via {@link org.eclipse.jetty.client.api.Request#getConnection()}.</p>
+     *
+     * @param happyEyeballs whether connection attempts to hosts with multiple addresses are raced
+     */
+    public void setHappyEyeballs(boolean happyEyeballs)
+    {
+        this.happyEyeballs = happyEyeballs;
+    }
+
+    /**
+     * @return the delay before starting the next connection attempt when racing connection attempts
+     */
+    @ManagedAttribute("The delay before starting the next connection attempt when racing connection attempts")
+    public Duration getConnectionAttemptDelay()
+    {
+        return connectionAttemptDelay;
+    }
+
+    /**
+     * @param connectionAttemptDelay the delay before starting the next connection attempt
+     * when racing connection attempts; RFC 8305 recommends 250 ms
+     */
+    public void setConnectionAttemptDelay(Duration connectionAttemptDelay)
+    {
+        this.connectionAttemptDelay = Objects.requireNonNull(connectionAttemptDelay);
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -78,4 +141,164 @@ public abstract class AbstractConnectorHttpClientTransport extends AbstractHttpC
     {
         connect((SocketAddress)address, context);
     }
+
+    /**
+     * <p>Connects to one of the given addresses, racing the connection attempts
+     * if {@link #isHappyEyeballs() happy eyeballs} is enabled, otherwise trying
+     * the addresses sequentially.</p>
+     *
+     * @param addresses the resolved addresses of the host
+     * @param context the context information to establish the connection
+     */
+    public void connect(List<InetSocketAddress> addresses, Map<String, Object> context)
+    {
+        new HappyEyeballsConnect(happyEyeballs ? sortAddresses(addresses) : addresses, context).attempt();
+    }
+
+    /**
+     * <p>Sorts the given addresses alternating the address families, starting
+     * with the family of the first address, as defined by RFC 8305, section 4.</p>
+     *
+     * @param addresses the addresses to sort
+     * @return the sorted addresses
+     */
+    static List<InetSocketAddress> sortAddresses(List<InetSocketAddress> addresses)
+    {
+        if (addresses.isEmpty())
+            return addresses;
+        boolean firstIPv6 = addresses.get(0).getAddress() instanceof Inet6Address;
+        List<InetSocketAddress> first = new ArrayList<>();
+        List<InetSocketAddress> second = new ArrayList<>();
+        for (InetSocketAddress address : addresses)
+        {
+            boolean ipv6 = address.getAddress() instanceof Inet6Address;
+            (ipv6 == firstIPv6 ? first : second).add(address);
+        }
+        List<InetSocketAddress> result = new ArrayList<>(addresses.size());
+        for (int i = 0; i < Math.max(first.size(), second.size()); ++i)
+        {
+            if (i < first.size())
+                result.add(first.get(i));
+            if (i < second.size())
+                result.add(second.get(i));
+        }
+        return result;
+    }
+
+    private class HappyEyeballsConnect
+    {
+        private final AutoLock lock = new AutoLock();
+        private final List<InetSocketAddress> addresses;
+        private final Map<String, Object> context;
+        private final Promise<Connection> promise;
+        private int next;
+        private int pending;
+        private boolean complete;
+        private Scheduler.Task task;
+        private Throwable failure;
+
+        @SuppressWarnings("unchecked")
+        private HappyEyeballsConnect(List<InetSocketAddress> addresses, Map<String, Object> context)
+        {
+            this.addresses = addresses;
+            this.context = context;
+            this.promise = (Promise<Connection>)context.get(HTTP_CONNECTION_PROMISE_CONTEXT_KEY);
+        }
+
+        private void attempt()
+        {
+            InetSocketAddress address;
+            try (AutoLock l = lock.lock())
+            {
+                if (complete || next == addresses.size())
+                    return;
+                address = addresses.get(next++);
+                ++pending;
+                if (task != null)
+                    task.cancel();
+                task = null;
+                if (next < addresses.size() && happyEyeballs)
+                    task = getHttpClient().getScheduler().schedule(this::attempt, connectionAttemptDelay.toNanos(), TimeUnit.NANOSECONDS);
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Connecting to {} for {}", address, this);
+
+            // Each attempt has its own context, as the connection is created from it.
+            Map<String, Object> attemptContext = new ConcurrentHashMap<>(context);
+            attemptContext.put(HTTP_CONNECTION_PROMISE_CONTEXT_KEY, new Promise<Connection>()
+            {
+                @Override
+                public void succeeded(Connection connection)
+                {
+                    onConnected(address, connection);
+                }
+
+                @Override
+                public void failed(Throwable x)
+                {
+                    onFailed(address, x);
+                }
+            });
+            connect((SocketAddress)address, attemptContext);
+        }
+
+        private void onConnected(InetSocketAddress address, Connection connection)
+        {
+            boolean winner;
+            try (AutoLock l = lock.lock())
+            {
+                --pending;
+                winner = !complete;
+                complete = true;
+                if (task != null)
+                    task.cancel();
+                task = null;
+            }
+            if (winner)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Connected to {} for {}", address, this);
+                promise.succeeded(connection);
+            }
+            else
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Closing late connection to {} for {}", address, this);
+                connection.close();
+            }
+        }
+
+        private void onFailed(InetSocketAddress address, Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failed connection to {} for {}", address, this, x);
+            Throwable result = null;
+            try (AutoLock l = lock.lock())
+            {
+                --pending;
+                if (complete)
+                    return;
+                if (failure == null)
+                    failure = x;
+                else if (failure != x)
+                    failure.addSuppressed(x);
+                if (next == addresses.size() && pending == 0)
+                {
+                    complete = true;
+                    result = failure;
+                }
+            }
+            if (result != null)
+                promise.failed(result);
+            else
+                attempt();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), addresses);
+        }
+    }
 }
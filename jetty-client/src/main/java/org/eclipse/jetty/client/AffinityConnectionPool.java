@@ -0,0 +1,181 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Objects;
+
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.Pool;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ConnectionPool} that provides requests with the same affinity
+ * key with the same connection, as long as it is open and available.</p>
+ * <p>The affinity key is the value of the request attribute with the name
+ * returned by {@link #getAffinityAttribute()}, for example the user identifier,
+ * so that the requests of a user are sent over a connection that a proxy has
+ * already associated with a warm backend connection.</p>
+ * <p>Connections are selected with consistent (rendezvous) hashing of the affinity
+ * key, so that only the keys associated with a connection move to other connections
+ * when that connection is closed, while the other keys keep their connection.
+ * When the connection associated with a key is not available, for example
+ * because it reached its max multiplex, the request is sent over the
+ * next connection for that key, and the {@link Listener}s are notified.
+ * Requests without the affinity attribute are sent over a random connection.</p>
+ * <p>Applications using this class should {@link #preCreateConnections(int) pre-create}
+ * the connections, otherwise keys are associated with the few connections initially
+ * opened and then move to the connections opened later.</p>
+ * <p>The affinity key is taken from the request at the head of the destination queue:
+ * when requests are sent concurrently, a request may occasionally be sent over
+ * the connection selected for another request.</p>
+ */
+@ManagedObject
+public class AffinityConnectionPool extends MultiplexConnectionPool
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AffinityConnectionPool.class);
+
+    private final HttpDestination destination;
+    private final String affinityAttribute;
+
+    public AffinityConnectionPool(HttpDestination destination, int maxConnections, Callback requester, int maxMultiplex, String affinityAttribute)
+    {
+        super(destination, Pool.StrategyType.RANDOM, maxConnections, false, requester, maxMultiplex);
+        this.destination = destination;
+        this.affinityAttribute = Objects.requireNonNull(affinityAttribute);
+    }
+
+    /**
+     * @return the name of the request attribute whose value is the affinity key
+     */
+    @ManagedAttribute(value = "The name of the request attribute whose value is the affinity key", readonly = true)
+    public String getAffinityAttribute()
+    {
+        return affinityAttribute;
+    }
+
+    @Override
+    protected Pool<Connection>.Entry acquireEntry(Pool<Connection> pool)
+    {
+        Object key = getAffinityKey();
+        if (key == null)
+            return super.acquireEntry(pool);
+
+        int hash = key.hashCode();
+        List<Weight> weights = new ArrayList<>();
+        for (Pool<Connection>.Entry entry : pool.values())
+        {
+            if (!entry.isReserved() && !entry.isClosed())
+                weights.add(new Weight(entry, hash));
+        }
+        weights.sort(null);
+
+        for (int i = 0; i < weights.size(); ++i)
+        {
+            Pool<Connection>.Entry entry = weights.get(i).entry;
+            if (pool.acquire(entry))
+            {
+                boolean preferred = i == 0;
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Affinity key {} acquired {} {}", key, preferred ? "preferred" : "fallback", entry);
+                notifyAffinity(key, entry.getPooled(), preferred);
+                return entry;
+            }
+        }
+        return null;
+    }
+
+    /**
+     * @return the affinity key of the next request to send, or {@code null} if the request has no affinity key
+     */
+    protected Object getAffinityKey()
+    {
+        HttpExchange exchange = destination.getHttpExchanges().peek();
+        if (exchange == null)
+            return null;
+        return exchange.getRequest().getAttributes().get(affinityAttribute);
+    }
+
+    private void notifyAffinity(Object key, Connection connection, boolean preferred)
+    {
+        for (AbstractConnectionPool.Listener listener : getListeners())
+        {
+            if (listener instanceof Listener)
+            {
+                try
+                {
+                    ((Listener)listener).onAffinity(this, key, connection, preferred);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", listener, x);
+                }
+            }
+        }
+    }
+
+    /**
+     * <p>A listener of the decisions of an {@link AffinityConnectionPool}.</p>
+     */
+    public interface Listener extends AbstractConnectionPool.Listener
+    {
+        /**
+         * <p>Callback method invoked when the pool selects a connection for a request with an affinity key.</p>
+         *
+         * @param pool the pool
+         * @param key the affinity key
+         * @param connection the connection selected
+         * @param preferred whether the connection is the one associated with the key,
+         * or {@code false} if that connection was not available and another one was selected
+         */
+        public default void onAffinity(AffinityConnectionPool pool, Object key, Connection connection, boolean preferred)
+        {
+        }
+    }
+
+    private static class Weight implements Comparable<Weight>
+    {
+        private final Pool<Connection>.Entry entry;
+        private final long weight;
+
+        private Weight(Pool<Connection>.Entry entry, int hash)
+        {
+            this.entry = entry;
+            this.weight = mix(((long)hash << 32) | (System.identityHashCode(entry) & 0xFFFFFFFFL));
+        }
+
+        private static long mix(long value)
+        {
+            // The finalizer of the 64-bit MurmurHash3.
+            value ^= value >>> 33;
+            value *= 0xFF51AFD7ED558CCDL;
+            value ^= value >>> 33;
+            value *= 0xC4CEB9FE1A85EC53L;
+            value ^= value >>> 33;
+            return value;
+        }
+
+        @Override
+        public int compareTo(Weight that)
+        {
+            // Highest weight first.
+            return Long.compare(that.weight, weight);
+        }
+    }
+}
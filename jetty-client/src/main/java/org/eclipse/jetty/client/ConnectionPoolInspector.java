@@ -0,0 +1,540 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.net.SocketAddress;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Objects;
+import java.util.function.Predicate;
+import java.util.stream.Collectors;
+import javax.net.ssl.SSLSession;
+
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.client.api.Destination;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Inspects the destinations of a {@link HttpClient}, their pooled connections
+ * and their queued requests, typically to troubleshoot the exhaustion of connection pools.</p>
+ * <p>The information returned is a snapshot taken while the client is running,
+ * so it may be stale as soon as it is returned.
+ * Connections may be selectively {@link #close(ConnectionInfo) closed}, failing the
+ * exchanges in progress on them, or {@link #quarantine(ConnectionInfo) quarantined},
+ * so that they are not used for new exchanges and are closed when the exchanges
+ * in progress on them complete.</p>
+ * <p>Only connection pools that extend {@link AbstractConnectionPool} expose their connections.</p>
+ * <p>To inspect the connection pools via JMX, add this object as a bean to the client:</p>
+ * <pre>
+ * HttpClient httpClient = new HttpClient();
+ * httpClient.addBean(new ConnectionPoolInspector(httpClient));
+ * httpClient.addBean(mbeanContainer);
+ * </pre>
+ */
+@ManagedObject("Inspects the destinations, the pooled connections and the queued requests of HttpClient")
+public class ConnectionPoolInspector
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ConnectionPoolInspector.class);
+
+    private final HttpClient client;
+
+    public ConnectionPoolInspector(HttpClient client)
+    {
+        this.client = Objects.requireNonNull(client);
+    }
+
+    public HttpClient getHttpClient()
+    {
+        return client;
+    }
+
+    @ManagedAttribute(value = "The number of destinations", readonly = true)
+    public int getDestinationCount()
+    {
+        return client.getDestinations().size();
+    }
+
+    /**
+     * @return a snapshot of the destinations of the client
+     */
+    public List<DestinationInfo> getDestinations()
+    {
+        List<DestinationInfo> infos = new ArrayList<>();
+        for (Destination destination : client.getDestinations())
+        {
+            if (destination instanceof HttpDestination)
+                infos.add(new DestinationInfo((HttpDestination)destination));
+        }
+        return infos;
+    }
+
+    /**
+     * @return a snapshot of the connections of all the destinations of the client
+     */
+    public List<ConnectionInfo> getConnections()
+    {
+        return getDestinations().stream()
+            .flatMap(destination -> destination.getConnections().stream())
+            .collect(Collectors.toList());
+    }
+
+    /**
+     * @param id the connection id, as returned by {@link ConnectionInfo#getId()}
+     * @return the connection with the given id, or {@code null} if there is no such connection
+     */
+    public ConnectionInfo findConnection(String id)
+    {
+        return getConnections().stream()
+            .filter(connection -> connection.getId().equals(id))
+            .findFirst()
+            .orElse(null);
+    }
+
+    /**
+     * <p>Closes the given connection, failing the exchanges in progress on it.</p>
+     *
+     * @param info the connection to close
+     * @return whether the connection has been closed by this call
+     */
+    public boolean close(ConnectionInfo info)
+    {
+        Connection connection = info.getConnection();
+        if (connection.isClosed())
+            return false;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Closing {}", info);
+        connection.close();
+        return true;
+    }
+
+    /**
+     * <p>Closes the connections that match the given filter, failing the exchanges in progress on them.</p>
+     *
+     * @param filter the filter selecting the connections to close
+     * @return the number of connections closed
+     */
+    public int close(Predicate<ConnectionInfo> filter)
+    {
+        int closed = 0;
+        for (ConnectionInfo info : getConnections())
+        {
+            if (filter.test(info) && close(info))
+                ++closed;
+        }
+        return closed;
+    }
+
+    /**
+     * <p>Quarantines the given connection, so that it is not used for new
+     * exchanges and it is closed when the exchanges in progress on it complete.</p>
+     *
+     * @param info the connection to quarantine
+     * @return whether the connection has been quarantined by this call
+     * @see AbstractConnectionPool#quarantine(Connection)
+     */
+    public boolean quarantine(ConnectionInfo info)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Quarantining {}", info);
+        return info.pool.quarantine(info.getConnection());
+    }
+
+    /**
+     * <p>Quarantines the connections that match the given filter.</p>
+     *
+     * @param filter the filter selecting the connections to quarantine
+     * @return the number of connections quarantined
+     * @see #quarantine(ConnectionInfo)
+     */
+    public int quarantine(Predicate<ConnectionInfo> filter)
+    {
+        int quarantined = 0;
+        for (ConnectionInfo info : getConnections())
+        {
+            if (filter.test(info) && quarantine(info))
+                ++quarantined;
+        }
+        return quarantined;
+    }
+
+    @ManagedOperation(value = "Lists the destinations with their connections and queued requests", impact = "INFO")
+    public String inspect()
+    {
+        StringBuilder builder = new StringBuilder();
+        for (DestinationInfo destination : getDestinations())
+        {
+            builder.append(destination).append(System.lineSeparator());
+            for (ConnectionInfo connection : destination.getConnections())
+            {
+                builder.append("  ").append(connection).append(System.lineSeparator());
+            }
+            for (RequestInfo request : destination.getQueuedRequests())
+            {
+                builder.append("  ").append(request).append(System.lineSeparator());
+            }
+        }
+        return builder.toString();
+    }
+
+    @ManagedOperation(value = "Closes the connection with the given id", impact = "ACTION")
+    public boolean closeConnection(@Name(value = "id", description = "The connection id") String id)
+    {
+        ConnectionInfo info = findConnection(id);
+        return info != null && close(info);
+    }
+
+    @ManagedOperation(value = "Quarantines the connection with the given id", impact = "ACTION")
+    public boolean quarantineConnection(@Name(value = "id", description = "The connection id") String id)
+    {
+        ConnectionInfo info = findConnection(id);
+        return info != null && quarantine(info);
+    }
+
+    @ManagedOperation(value = "Quarantines the connections of the destination with the given origin", impact = "ACTION")
+    public int quarantineDestination(@Name(value = "origin", description = "The destination origin, for example http://localhost:8080") String origin)
+    {
+        return quarantine(info -> info.getDestination().equals(origin));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), client);
+    }
+
+    /**
+     * <p>A snapshot of a destination, with its connections and queued requests.</p>
+     */
+    public static class DestinationInfo
+    {
+        private final String origin;
+        private final boolean secure;
+        private final String proxy;
+        private final int connectionCount;
+        private final int maxConnectionCount;
+        private final int pendingConnectionCount;
+        private final List<ConnectionInfo> connections;
+        private final List<RequestInfo> queuedRequests;
+
+        private DestinationInfo(HttpDestination destination)
+        {
+            this.origin = destination.asString();
+            this.secure = destination.isSecure();
+            ProxyConfiguration.Proxy proxy = destination.getProxy();
+            this.proxy = proxy == null ? null : proxy.getAddress().asString();
+            ConnectionPool connectionPool = destination.getConnectionPool();
+            if (connectionPool instanceof AbstractConnectionPool)
+            {
+                AbstractConnectionPool pool = (AbstractConnectionPool)connectionPool;
+                this.connectionCount = pool.getConnectionCount();
+                this.maxConnectionCount = pool.getMaxConnectionCount();
+                this.pendingConnectionCount = pool.getPendingConnectionCount();
+                this.connections = pool.getConnectionInfos();
+            }
+            else
+            {
+                this.connectionCount = -1;
+                this.maxConnectionCount = -1;
+                this.pendingConnectionCount = -1;
+                this.connections = List.of();
+            }
+            List<RequestInfo> queued = new ArrayList<>();
+            for (HttpExchange exchange : destination.getHttpExchanges())
+            {
+                queued.add(new RequestInfo(exchange));
+            }
+            this.queuedRequests = queued;
+        }
+
+        /**
+         * @return the origin of the destination, for example {@code http://localhost:8080}
+         */
+        public String getOrigin()
+        {
+            return origin;
+        }
+
+        public boolean isSecure()
+        {
+            return secure;
+        }
+
+        /**
+         * @return the address of the proxy used by the destination, or {@code null} if no proxy is used
+         */
+        public String getProxy()
+        {
+            return proxy;
+        }
+
+        /**
+         * @return the number of connections, or {@code -1} if the connection pool cannot be inspected
+         */
+        public int getConnectionCount()
+        {
+            return connectionCount;
+        }
+
+        /**
+         * @return the max number of connections, or {@code -1} if the connection pool cannot be inspected
+         */
+        public int getMaxConnectionCount()
+        {
+            return maxConnectionCount;
+        }
+
+        /**
+         * @return the number of connections being opened, or {@code -1} if the connection pool cannot be inspected
+         */
+        public int getPendingConnectionCount()
+        {
+            return pendingConnectionCount;
+        }
+
+        public List<ConnectionInfo> getConnections()
+        {
+            return connections;
+        }
+
+        /**
+         * @return the requests waiting for a connection
+         */
+        public List<RequestInfo> getQueuedRequests()
+        {
+            return queuedRequests;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s[secure=%b,proxy=%s,c=%d/%d/%d,q=%d]",
+                origin,
+                secure,
+                proxy,
+                pendingConnectionCount,
+                connectionCount,
+                maxConnectionCount,
+                queuedRequests.size());
+        }
+    }
+
+    /**
+     * <p>A snapshot of a pooled connection.</p>
+     */
+    public static class ConnectionInfo
+    {
+        private final AbstractConnectionPool pool;
+        private final Connection connection;
+        private final String id;
+        private final String protocol;
+        private final SocketAddress localAddress;
+        private final SocketAddress remoteAddress;
+        private final SSLSession sslSession;
+        private final long age;
+        private final long idleTime;
+        private final int activeRequests;
+        private final int maxMultiplex;
+        private final boolean quarantined;
+        private final String destination;
+
+        ConnectionInfo(AbstractConnectionPool pool, String destination, Connection connection, long age, long idleTime, int activeRequests, int maxMultiplex, boolean quarantined)
+        {
+            this.pool = pool;
+            this.destination = destination;
+            this.connection = connection;
+            this.id = String.format("%s@%x", connection.getClass().getSimpleName(), connection.hashCode());
+            IConnection iConnection = connection instanceof IConnection ? (IConnection)connection : null;
+            this.protocol = iConnection == null ? null : iConnection.getProtocol();
+            this.sslSession = iConnection == null ? null : iConnection.getSSLSession();
+            this.localAddress = connection.getLocalSocketAddress();
+            this.remoteAddress = connection.getRemoteSocketAddress();
+            this.age = age;
+            this.idleTime = idleTime;
+            this.activeRequests = activeRequests;
+            this.maxMultiplex = maxMultiplex;
+            this.quarantined = quarantined;
+        }
+
+        public Connection getConnection()
+        {
+            return connection;
+        }
+
+        /**
+         * @return the id of the connection, as used by {@link ConnectionPoolInspector#closeConnection(String)}
+         */
+        public String getId()
+        {
+            return id;
+        }
+
+        /**
+         * @return the origin of the destination of the connection
+         */
+        public String getDestination()
+        {
+            return destination;
+        }
+
+        /**
+         * @return the protocol spoken by the connection, or {@code null} if unknown
+         * @see IConnection#getProtocol()
+         */
+        public String getProtocol()
+        {
+            return protocol;
+        }
+
+        public SocketAddress getLocalAddress()
+        {
+            return localAddress;
+        }
+
+        public SocketAddress getRemoteAddress()
+        {
+            return remoteAddress;
+        }
+
+        /**
+         * @return the TLS session of the connection, or {@code null} if the connection is not secured by TLS
+         */
+        public SSLSession getSSLSession()
+        {
+            return sslSession;
+        }
+
+        /**
+         * @return the time in milliseconds since the connection was opened
+         */
+        public long getAge()
+        {
+            return age;
+        }
+
+        /**
+         * @return the time in milliseconds since the connection was last used,
+         * or {@code 0} if the connection is in use
+         */
+        public long getIdleTime()
+        {
+            return idleTime;
+        }
+
+        /**
+         * @return the number of exchanges (for multiplexed protocols, streams) in progress on the connection
+         */
+        public int getActiveRequests()
+        {
+            return activeRequests;
+        }
+
+        /**
+         * @return the max number of exchanges that can be in progress on the connection
+         */
+        public int getMaxMultiplex()
+        {
+            return maxMultiplex;
+        }
+
+        public boolean isIdle()
+        {
+            return activeRequests == 0;
+        }
+
+        /**
+         * @return whether the connection is quarantined, or draining, and will be closed when idle
+         */
+        public boolean isQuarantined()
+        {
+            return quarantined;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s[%s,l:%s<->r:%s,%s,age=%dms,idle=%dms,active=%d/%d,quarantined=%b,tls=%s]",
+                id,
+                protocol,
+                localAddress,
+                remoteAddress,
+                isIdle() ? "IDLE" : "ACTIVE",
+                age,
+                idleTime,
+                activeRequests,
+                maxMultiplex,
+                quarantined,
+                sslSession == null ? null : sslSession.getProtocol() + "/" + sslSession.getCipherSuite());
+        }
+    }
+
+    /**
+     * <p>A snapshot of a request waiting for a connection.</p>
+     */
+    public static class RequestInfo
+    {
+        private final String method;
+        private final String uri;
+        private final String upgradeProtocol;
+        private final long queuedTime;
+
+        private RequestInfo(HttpExchange exchange)
+        {
+            HttpRequest request = exchange.getRequest();
+            this.method = request.getMethod();
+            this.uri = String.valueOf(request.getURI());
+            String upgrade = request.getUpgradeProtocol();
+            this.upgradeProtocol = upgrade != null ? upgrade : request.getHeaders().get(HttpHeader.UPGRADE);
+            this.queuedTime = NanoTime.millisSince(exchange.getCreationNanoTime());
+        }
+
+        public String getMethod()
+        {
+            return method;
+        }
+
+        public String getURI()
+        {
+            return uri;
+        }
+
+        /**
+         * @return the protocol the request upgrades to, for example {@code websocket}, or {@code null}
+         */
+        public String getUpgradeProtocol()
+        {
+            return upgradeProtocol;
+        }
+
+        /**
+         * @return the time in milliseconds the request has been waiting for a connection
+         */
+        public long getQueuedTime()
+        {
+            return queuedTime;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s %s[upgrade=%s,queued=%dms]", method, uri, upgradeProtocol, queuedTime);
+        }
+    }
+}
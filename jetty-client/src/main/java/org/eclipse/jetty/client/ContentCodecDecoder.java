@@ -0,0 +1,78 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.component.Destroyable;
+
+/**
+ * <p>A {@link ContentDecoder} backed by a {@link ContentCodec}.</p>
+ * <p>{@link HttpClient} configures a {@link Factory} for every {@link ContentCodec}
+ * discovered by the {@link java.util.ServiceLoader} that supports decoding,
+ * so that the same codecs used by the server are available to decode responses.</p>
+ */
+public class ContentCodecDecoder implements ContentDecoder, Destroyable
+{
+    private final ContentCodec.Decoder decoder;
+
+    public ContentCodecDecoder(ContentCodec.Decoder decoder)
+    {
+        this.decoder = decoder;
+    }
+
+    @Override
+    public ByteBuffer decode(ByteBuffer buffer)
+    {
+        return decoder.decode(buffer);
+    }
+
+    @Override
+    public void release(ByteBuffer decoded)
+    {
+        decoder.release(decoded);
+    }
+
+    @Override
+    public void destroy()
+    {
+        decoder.destroy();
+    }
+
+    public static class Factory extends ContentDecoder.Factory
+    {
+        private final ContentCodec codec;
+        private final ByteBufferPool byteBufferPool;
+
+        public Factory(ContentCodec codec, ByteBufferPool byteBufferPool)
+        {
+            super(codec.getEncoding());
+            this.codec = codec;
+            this.byteBufferPool = byteBufferPool;
+        }
+
+        public ContentCodec getContentCodec()
+        {
+            return codec;
+        }
+
+        @Override
+        public ContentDecoder newContentDecoder()
+        {
+            return new ContentCodecDecoder(codec.newDecoder(byteBufferPool));
+        }
+    }
+}
@@ -29,7 +29,12 @@ public class DuplexConnectionPool extends AbstractConnectionPool
 
     public DuplexConnectionPool(HttpDestination destination, int maxConnections, boolean cache, Callback requester)
     {
-        super(destination, Pool.StrategyType.FIRST, maxConnections, cache, requester);
+        this(destination, Pool.StrategyType.FIRST, maxConnections, cache, requester);
+    }
+
+    public DuplexConnectionPool(HttpDestination destination, Pool.StrategyType strategy, int maxConnections, boolean cache, Callback requester)
+    {
+        super(destination, strategy, maxConnections, cache, requester);
     }
 
     @Deprecated
@@ -23,9 +23,11 @@ import java.net.SocketAddress;
 import java.net.URI;
 import java.nio.channels.SelectionKey;
 import java.nio.channels.SocketChannel;
+import java.nio.file.Path;
 import java.time.Duration;
 import java.util.ArrayList;
 import java.util.Collection;
+import java.util.Collections;
 import java.util.HashSet;
 import java.util.Iterator;
 import java.util.List;
@@ -33,8 +35,10 @@ import java.util.Locale;
 import java.util.Map;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.ExecutionException;
 import java.util.concurrent.Executor;
 import java.util.concurrent.TimeoutException;
@@ -47,6 +51,7 @@ import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.client.api.Response;
 import org.eclipse.jetty.client.http.HttpClientTransportOverHTTP;
 import org.eclipse.jetty.client.util.FormRequestContent;
+import org.eclipse.jetty.http.ContentCodec;
 import org.eclipse.jetty.http.HttpCompliance;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpHeader;
@@ -59,6 +64,8 @@ import org.eclipse.jetty.io.ClientConnectionFactory;
 import org.eclipse.jetty.io.ClientConnector;
 import org.eclipse.jetty.io.MappedByteBufferPool;
 import org.eclipse.jetty.io.RetainableByteBufferPool;
+import org.eclipse.jetty.io.dns.DnsRecord;
+import org.eclipse.jetty.io.dns.DnsResolver;
 import org.eclipse.jetty.io.ssl.SslClientConnectionFactory;
 import org.eclipse.jetty.util.Fields;
 import org.eclipse.jetty.util.Jetty;
@@ -123,6 +130,7 @@ public class HttpClient extends ContainerLifeCycle
     private final ConcurrentMap<Origin, HttpDestination> destinations = new ConcurrentHashMap<>();
     private final ProtocolHandlers handlers = new ProtocolHandlers();
     private final List<Request.Listener> requestListeners = new ArrayList<>();
+    private final List<Interceptor> interceptors = new CopyOnWriteArrayList<>();
     private final Set<ContentDecoder.Factory> decoderFactories = new ContentDecoderFactorySet();
     private final ProxyConfiguration proxyConfig = new ProxyConfiguration();
     private final HttpClientTransport transport;
@@ -149,7 +157,12 @@ public class HttpClient extends ContainerLifeCycle
     private boolean useInputDirectByteBuffers = true;
     private boolean useOutputDirectByteBuffers = true;
     private int maxResponseHeadersSize = -1;
+    private long maxConnectionDuration;
+    private long connectionReplacementMargin;
+    private boolean keepAliveHintsEnabled = true;
     private Sweeper destinationSweeper;
+    private RetryPolicy retryPolicy;
+    private boolean echEnabled;
 
     /**
      * Creates a HttpClient instance that can perform HTTP/1.1 requests to non-TLS and TLS destinations.
@@ -171,7 +184,7 @@ public class HttpClient extends ContainerLifeCycle
     @Override
     public void dump(Appendable out, String indent) throws IOException
     {
-        dumpObjects(out, indent, new DumpableCollection("requestListeners", requestListeners));
+        dumpObjects(out, indent, new DumpableCollection("requestListeners", requestListeners), new DumpableCollection("interceptors", interceptors));
     }
 
     public HttpClientTransport getTransport()
@@ -223,6 +236,11 @@ public class HttpClient extends ContainerLifeCycle
         handlers.put(new UpgradeProtocolHandler());
 
         decoderFactories.add(new GZIPContentDecoder.Factory(byteBufferPool));
+        for (ContentCodec codec : ContentCodec.getContentCodecs())
+        {
+            if (codec.isDecodingSupported())
+                decoderFactories.add(new ContentCodecDecoder.Factory(codec, byteBufferPool));
+        }
 
         cookieManager = newCookieManager();
         cookieStore = cookieManager.getCookieStore();
@@ -279,6 +297,36 @@ public class HttpClient extends ContainerLifeCycle
         return requestListeners;
     }
 
+    /**
+     * <p>Adds an interceptor at the end of the interceptor chain.</p>
+     * <p>Every request sent by this HttpClient is intercepted by the interceptors,
+     * in the order they have been added.</p>
+     *
+     * @param interceptor the interceptor to add
+     * @see #removeInterceptor(Interceptor)
+     */
+    public void addInterceptor(Interceptor interceptor)
+    {
+        interceptors.add(Objects.requireNonNull(interceptor));
+    }
+
+    /**
+     * @param interceptor the interceptor to remove
+     * @return whether the interceptor was removed
+     */
+    public boolean removeInterceptor(Interceptor interceptor)
+    {
+        return interceptors.remove(interceptor);
+    }
+
+    /**
+     * @return a read-only list of the interceptors, in the order they are invoked
+     */
+    public List<Interceptor> getInterceptors()
+    {
+        return Collections.unmodifiableList(interceptors);
+    }
+
     /**
      * @return the cookie store associated with this instance
      */
@@ -478,12 +526,26 @@ public class HttpClient extends ContainerLifeCycle
     {
         HttpClientTransport transport = getTransport();
         Origin origin = transport.newOrigin((HttpRequest)request);
+        // Destinations reached via Unix-Domain sockets are local, so they are not proxied.
+        if (origin.getUnixDomainPath() == null)
+            origin = proxied(origin, getProxyConfiguration().select(origin, request.getProxies()));
         HttpDestination destination = resolveDestination(origin);
         if (LOG.isDebugEnabled())
             LOG.debug("Resolved {} for {}", destination, request);
         return destination;
     }
 
+    Origin proxied(Origin origin, ProxyConfiguration.Proxy proxy)
+    {
+        // The origin specifies the proxy only if it is not
+        // the one that the proxy configuration would match,
+        // so that by default there is one destination per origin.
+        Origin result = origin.getProxy() == null ? origin : new Origin(origin.getScheme(), origin.getAddress(), origin.getTag(), origin.getProtocol(), origin.getUnixDomainPath());
+        if (proxy == null || proxy == getProxyConfiguration().match(result))
+            return result;
+        return new Origin(origin.getScheme(), origin.getAddress(), origin.getTag(), origin.getProtocol(), origin.getUnixDomainPath(), proxy);
+    }
+
     public Origin createOrigin(HttpRequest request, Origin.Protocol protocol)
     {
         String scheme = request.getScheme();
@@ -495,7 +557,7 @@ public class HttpClient extends ContainerLifeCycle
         host = host.toLowerCase(Locale.ENGLISH);
         int port = request.getPort();
         port = normalizePort(scheme, port);
-        return new Origin(scheme, host, port, request.getTag(), protocol);
+        return new Origin(scheme, new Origin.Address(host, port), request.getTag(), protocol, request.getUnixDomainPath());
     }
 
     /**
@@ -521,6 +583,22 @@ public class HttpClient extends ContainerLifeCycle
         });
     }
 
+    /**
+     * <p>Pre-creates up to {@code connectionCount} connections to the destination
+     * of the given URI, typically at startup, so that latency sensitive
+     * applications do not pay the connection setup cost on the first requests.</p>
+     *
+     * @param uri the URI identifying the destination
+     * @param connectionCount the number of connections to pre-create
+     * @return a {@link CompletableFuture} completed when the connections are ready
+     * @see HttpDestination#preCreateConnections(int)
+     */
+    public CompletableFuture<Void> preCreateConnections(String uri, int connectionCount)
+    {
+        HttpDestination destination = (HttpDestination)resolveDestination(newRequest(uri));
+        return destination.preCreateConnections(connectionCount);
+    }
+
     protected boolean removeDestination(HttpDestination destination)
     {
         boolean removed = destinations.remove(destination.getOrigin(), destination);
@@ -539,6 +617,23 @@ public class HttpClient extends ContainerLifeCycle
     }
 
     protected void send(HttpRequest request, List<Response.ResponseListener> listeners)
+    {
+        RetryPolicy retryPolicy = request.getRetryPolicy();
+        if (retryPolicy == null)
+            intercept(request, listeners);
+        else
+            retryPolicy.send(request, listeners, this::intercept);
+    }
+
+    private void intercept(HttpRequest request, List<Response.ResponseListener> listeners)
+    {
+        if (interceptors.isEmpty())
+            sendToDestination(request, listeners);
+        else
+            new InterceptorChain(List.copyOf(interceptors), this::sendToDestination, listeners).start(request);
+    }
+
+    private void sendToDestination(HttpRequest request, List<Response.ResponseListener> listeners)
     {
         HttpDestination destination = (HttpDestination)resolveDestination(request);
         destination.send(request, listeners);
@@ -555,11 +650,57 @@ public class HttpClient extends ContainerLifeCycle
         context.put(ClientConnector.APPLICATION_PROTOCOLS_CONTEXT_KEY, protocols);
 
         Origin.Address address = destination.getConnectAddress();
+        Path unixDomainPath = destination.getOrigin().getUnixDomainPath();
+        if (unixDomainPath != null)
+        {
+            // No need to resolve the host, the connection is established to the Unix-Domain path.
+            context.put(ClientConnector.UNIX_DOMAIN_PATH_CONTEXT_KEY, unixDomainPath);
+            context.put(HttpClientTransport.HTTP_CONNECTION_PROMISE_CONTEXT_KEY, promise);
+            transport.connect((SocketAddress)InetSocketAddress.createUnresolved(address.getHost(), address.getPort()), context);
+            return;
+        }
+
+        if (isECHEnabled() && isSchemeSecure(destination.getScheme()) && resolver instanceof DnsResolver)
+        {
+            // Look up the ECH configuration of the origin server, even when connecting via a proxy.
+            Origin.Address origin = destination.getOrigin().getAddress();
+            ((DnsResolver)resolver).resolveHttps(origin.getHost(), origin.getPort(), Promise.from(records ->
+            {
+                records.stream()
+                    .map(DnsRecord::getECHConfigList)
+                    .filter(Objects::nonNull)
+                    .findFirst()
+                    .ifPresent(echConfigList -> context.put(SslClientConnectionFactory.ECH_CONFIG_LIST_CONTEXT_KEY, echConfigList));
+                resolve(address, context, promise);
+            }, failure ->
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("No HTTPS records for {}", origin, failure);
+                resolve(address, context, promise);
+            }));
+            return;
+        }
+
+        resolve(address, context, promise);
+    }
+
+    private void resolve(Origin.Address address, Map<String, Object> context, Promise<Connection> promise)
+    {
         resolver.resolve(address.getHost(), address.getPort(), new Promise<>()
         {
             @Override
             public void succeeded(List<InetSocketAddress> socketAddresses)
             {
+                if (socketAddresses.size() > 1 && transport instanceof AbstractConnectorHttpClientTransport)
+                {
+                    AbstractConnectorHttpClientTransport connectorTransport = (AbstractConnectorHttpClientTransport)transport;
+                    if (connectorTransport.isHappyEyeballs())
+                    {
+                        context.put(HttpClientTransport.HTTP_CONNECTION_PROMISE_CONTEXT_KEY, promise);
+                        connectorTransport.connect(socketAddresses, context);
+                        return;
+                    }
+                }
                 connect(socketAddresses, 0, context);
             }
 
@@ -571,11 +712,22 @@ public class HttpClient extends ContainerLifeCycle
 
             private void connect(List<InetSocketAddress> socketAddresses, int index, Map<String, Object> context)
             {
+                InetSocketAddress socketAddress = socketAddresses.get(index);
                 context.put(HttpClientTransport.HTTP_CONNECTION_PROMISE_CONTEXT_KEY, new Promise.Wrapper<>(promise)
                 {
+                    @Override
+                    public void succeeded(Connection connection)
+                    {
+                        if (resolver instanceof ServiceDiscoveryResolver)
+                            ((ServiceDiscoveryResolver)resolver).onConnectSuccess(socketAddress);
+                        super.succeeded(connection);
+                    }
+
                     @Override
                     public void failed(Throwable x)
                     {
+                        if (resolver instanceof ServiceDiscoveryResolver)
+                            ((ServiceDiscoveryResolver)resolver).onConnectFailure(socketAddress, x);
                         int nextIndex = index + 1;
                         if (nextIndex == socketAddresses.size())
                             super.failed(x);
@@ -583,7 +735,7 @@ public class HttpClient extends ContainerLifeCycle
                             connect(socketAddresses, nextIndex, context);
                     }
                 });
-                transport.connect((SocketAddress)socketAddresses.get(index), context);
+                transport.connect((SocketAddress)socketAddress, context);
             }
         });
     }
@@ -754,6 +906,28 @@ public class HttpClient extends ContainerLifeCycle
         this.followRedirects = follow;
     }
 
+    /**
+     * @return the default policy to retry requests, or null if requests are not retried by default
+     * @see Request#getRetryPolicy()
+     */
+    public RetryPolicy getRetryPolicy()
+    {
+        return retryPolicy;
+    }
+
+    /**
+     * <p>Sets the default policy to retry requests, applied to the requests
+     * created after this method is called.</p>
+     *
+     * @param retryPolicy the default policy to retry requests, or null to not retry requests by default
+     * @see Request#retryPolicy(RetryPolicy)
+     */
+    public void setRetryPolicy(RetryPolicy retryPolicy)
+    {
+        updateBean(this.retryPolicy, retryPolicy);
+        this.retryPolicy = retryPolicy;
+    }
+
     /**
      * @return the {@link Executor} of this HttpClient
      */
@@ -857,6 +1031,79 @@ public class HttpClient extends ContainerLifeCycle
         this.maxRequestsQueuedPerDestination = maxRequestsQueuedPerDestination;
     }
 
+    /**
+     * @return the max duration in milliseconds a connection to a {@link Destination} can be used for
+     * @see #setMaxConnectionDuration(long)
+     */
+    @ManagedAttribute("The max duration in milliseconds a connection to a destination can be used for")
+    public long getMaxConnectionDuration()
+    {
+        return maxConnectionDuration;
+    }
+
+    /**
+     * <p>Sets the max duration in milliseconds a connection to a destination can be used for,
+     * after which the connection is closed when it is released, or when it is acquired while idle.</p>
+     * <p>This is the default {@link AbstractConnectionPool#setMaxDuration(long) max duration}
+     * of the connection pool of each destination, so that the servers behind a load balancer
+     * are rebalanced periodically, for example during a rolling restart.</p>
+     * <p>Destinations that need a different value can configure their connection pool directly.</p>
+     *
+     * @param maxConnectionDuration the max connection duration in milliseconds, or a non-positive value for no limit
+     */
+    public void setMaxConnectionDuration(long maxConnectionDuration)
+    {
+        this.maxConnectionDuration = maxConnectionDuration;
+    }
+
+    /**
+     * @return the time in milliseconds before the max connection duration at which connections are replaced
+     * @see #setConnectionReplacementMargin(long)
+     */
+    @ManagedAttribute("The time in milliseconds before the max connection duration at which connections are replaced")
+    public long getConnectionReplacementMargin()
+    {
+        return connectionReplacementMargin;
+    }
+
+    /**
+     * <p>Sets the time in milliseconds before the {@link #getMaxConnectionDuration() max connection duration}
+     * at which a connection that is released is closed and replaced by a new connection, so that
+     * requests do not wait for a new connection to be opened when the old one expires.</p>
+     * <p>This is the default {@link AbstractConnectionPool#setReplacementMargin(long) replacement margin}
+     * of the connection pool of each destination.</p>
+     *
+     * @param connectionReplacementMargin the connection replacement margin in milliseconds, or a non-positive value to disable replacement
+     */
+    public void setConnectionReplacementMargin(long connectionReplacementMargin)
+    {
+        this.connectionReplacementMargin = connectionReplacementMargin;
+    }
+
+    /**
+     * @return whether the {@code Keep-Alive} response header is honored for HTTP/1 connections
+     * @see #setKeepAliveHintsEnabled(boolean)
+     */
+    @ManagedAttribute("Whether the Keep-Alive response header is honored for HTTP/1 connections")
+    public boolean isKeepAliveHintsEnabled()
+    {
+        return keepAliveHintsEnabled;
+    }
+
+    /**
+     * <p>Sets whether the parameters of the {@code Keep-Alive} response header are honored
+     * for HTTP/1 connections.</p>
+     * <p>The {@code timeout} parameter lowers the idle timeout of the connection, so that the
+     * connection is closed by the client before the server closes it; the {@code max=0}
+     * parameter closes the connection as if the response had the {@code Connection: close} header.</p>
+     *
+     * @param keepAliveHintsEnabled whether the {@code Keep-Alive} response header is honored
+     */
+    public void setKeepAliveHintsEnabled(boolean keepAliveHintsEnabled)
+    {
+        this.keepAliveHintsEnabled = keepAliveHintsEnabled;
+    }
+
     /**
      * @return the size of the buffer (in bytes) used to write requests
      */
@@ -994,6 +1241,33 @@ public class HttpClient extends ContainerLifeCycle
         this.strictEventOrdering = strictEventOrdering;
     }
 
+    /**
+     * @return whether TLS Encrypted Client Hello is used
+     * @see #setECHEnabled(boolean)
+     */
+    @ManagedAttribute("Whether TLS Encrypted Client Hello is used")
+    public boolean isECHEnabled()
+    {
+        return echEnabled;
+    }
+
+    /**
+     * <p>Sets whether TLS Encrypted Client Hello is used for secure connections, so that
+     * the server name is not visible on the network.</p>
+     * <p>The ECH configuration of the server is obtained from its DNS {@code HTTPS} records,
+     * which requires the {@link #getSocketAddressResolver() SocketAddressResolver} to be a
+     * {@link DnsResolver}; ECH also requires support from the TLS provider, see
+     * {@link org.eclipse.jetty.io.ssl.ECHProcessor}.
+     * When a server does not publish an ECH configuration, the ClientHello is not encrypted,
+     * unless {@link SslContextFactory.Client#setECHGrease(boolean) GREASE} is enabled.</p>
+     *
+     * @param echEnabled whether TLS Encrypted Client Hello is used
+     */
+    public void setECHEnabled(boolean echEnabled)
+    {
+        this.echEnabled = echEnabled;
+    }
+
     /**
      * The default value is 0
      * @return the time in ms after which idle destinations are removed
@@ -105,6 +105,7 @@ public abstract class HttpConnection implements IConnection, Attachable
             SendFailure result;
             if (channel.associate(exchange))
             {
+                request.setConnection(this);
                 request.sent();
                 requestTimeouts.schedule(channel);
                 channel.send();
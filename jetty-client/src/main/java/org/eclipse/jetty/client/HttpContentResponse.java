@@ -17,6 +17,7 @@ import java.io.UnsupportedEncodingException;
 import java.nio.charset.StandardCharsets;
 import java.nio.charset.UnsupportedCharsetException;
 import java.util.List;
+import java.util.concurrent.CompletableFuture;
 
 import org.eclipse.jetty.client.api.ContentResponse;
 import org.eclipse.jetty.client.api.Request;
@@ -75,6 +76,18 @@ public class HttpContentResponse implements ContentResponse
         return response.getHeaders();
     }
 
+    @Override
+    public HttpFields getTrailers()
+    {
+        return response.getTrailers();
+    }
+
+    @Override
+    public CompletableFuture<HttpFields> getTrailersFuture()
+    {
+        return response.getTrailersFuture();
+    }
+
     @Override
     public boolean abort(Throwable cause)
     {
@@ -20,6 +20,8 @@ import java.util.ArrayList;
 import java.util.Iterator;
 import java.util.List;
 import java.util.Queue;
+import java.util.Set;
+import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.RejectedExecutionException;
 import java.util.concurrent.TimeoutException;
 
@@ -85,8 +87,9 @@ public abstract class HttpDestination extends ContainerLifeCycle implements Dest
             host += ":" + getPort();
         hostField = new HttpField(HttpHeader.HOST, host);
 
+        // Destinations reached via Unix-Domain sockets are local, so they are not proxied.
         ProxyConfiguration proxyConfig = client.getProxyConfiguration();
-        proxy = proxyConfig.match(origin);
+        proxy = origin.getUnixDomainPath() == null ? proxyConfig.match(origin) : null;
         ClientConnectionFactory connectionFactory = client.getTransport();
         if (proxy != null)
         {
@@ -156,6 +159,14 @@ public abstract class HttpDestination extends ContainerLifeCycle implements Dest
     protected void doStart() throws Exception
     {
         this.connectionPool = newConnectionPool(client);
+        if (connectionPool instanceof AbstractConnectionPool)
+        {
+            AbstractConnectionPool pool = (AbstractConnectionPool)connectionPool;
+            if (pool.getMaxDuration() <= 0)
+                pool.setMaxDuration(client.getMaxConnectionDuration());
+            if (pool.getReplacementMargin() <= 0)
+                pool.setReplacementMargin(client.getConnectionReplacementMargin());
+        }
         addBean(connectionPool, true);
         super.doStart();
         Sweeper connectionPoolSweeper = client.getBean(Sweeper.class);
@@ -282,6 +293,24 @@ public abstract class HttpDestination extends ContainerLifeCycle implements Dest
         return connectionPool;
     }
 
+    /**
+     * <p>Pre-creates up to {@code connectionCount} connections to this destination,
+     * so that they are ready to be used by the first requests.</p>
+     * <p>The returned {@link CompletableFuture} is completed when the connections
+     * are fully established, including the TLS handshake, the protocol negotiation
+     * and the protocol preface (for example, the HTTP/2 SETTINGS exchange).</p>
+     *
+     * @param connectionCount the number of connections to pre-create
+     * @return a {@link CompletableFuture} completed when the connections are ready
+     * @see ConnectionPool#preCreateConnections(int)
+     */
+    public CompletableFuture<Void> preCreateConnections(int connectionCount)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Pre-creating {} connections for {}", connectionCount, this);
+        return getConnectionPool().preCreateConnections(connectionCount);
+    }
+
     @Override
     public void succeeded()
     {
@@ -291,9 +320,34 @@ public abstract class HttpDestination extends ContainerLifeCycle implements Dest
     @Override
     public void failed(Throwable x)
     {
+        if (proxy != null)
+            failover(x);
         abort(x);
     }
 
+    private void failover(Throwable failure)
+    {
+        // Move the queued exchanges to the destination of the next
+        // healthy proxy, if any; the others are aborted by the caller.
+        ProxyConfiguration proxyConfig = client.getProxyConfiguration();
+        for (HttpExchange exchange : new ArrayList<>(exchanges))
+        {
+            HttpRequest request = exchange.getRequest();
+            Set<ProxyConfiguration.Proxy> failedProxies = request.getFailedProxies();
+            failedProxies.add(proxy);
+            ProxyConfiguration.Proxy next = proxyConfig.failover(origin, request.getProxies(), failedProxies);
+            if (next == null)
+                continue;
+            if (!exchanges.remove(exchange))
+                continue;
+            request.getConversation().getExchanges().remove(exchange);
+            HttpDestination destination = client.resolveDestination(client.proxied(origin, next));
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failing over {} from {} to {}", request, this, destination, failure);
+            destination.send(request, exchange.getResponseListeners());
+        }
+    }
+
     public void send(Request request, Response.CompleteListener listener)
     {
         ((HttpRequest)request).sendAsync(this, listener);
@@ -443,7 +497,29 @@ public abstract class HttpDestination extends ContainerLifeCycle implements Dest
     @Override
     public void newConnection(Promise<Connection> promise)
     {
-        createConnection(promise);
+        if (proxy == null)
+        {
+            createConnection(promise);
+            return;
+        }
+        // Track the health of the proxy.
+        ProxyConfiguration proxyConfig = client.getProxyConfiguration();
+        createConnection(new Promise.Wrapper<>(promise)
+        {
+            @Override
+            public void succeeded(Connection connection)
+            {
+                proxyConfig.succeeded(proxy);
+                super.succeeded(connection);
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                proxyConfig.failed(proxy, x);
+                super.failed(x);
+            }
+        });
     }
 
     protected void createConnection(Promise<Connection> promise)
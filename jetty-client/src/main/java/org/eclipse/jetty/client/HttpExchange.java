@@ -19,6 +19,7 @@ import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.client.api.Response;
 import org.eclipse.jetty.client.api.Result;
 import org.eclipse.jetty.io.CyclicTimeouts;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.thread.AutoLock;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
@@ -32,6 +33,7 @@ public class HttpExchange implements CyclicTimeouts.Expirable
     private final HttpRequest request;
     private final List<Response.ResponseListener> listeners;
     private final HttpResponse response;
+    private final long creationNanoTime = NanoTime.now();
     private State requestState = State.PENDING;
     private State responseState = State.PENDING;
     private HttpChannel _channel;
@@ -90,6 +92,14 @@ public class HttpExchange implements CyclicTimeouts.Expirable
         }
     }
 
+    /**
+     * @return the {@link NanoTime} at which this exchange was created
+     */
+    public long getCreationNanoTime()
+    {
+        return creationNanoTime;
+    }
+
     @Override
     public long getExpireNanoTime()
     {
@@ -415,6 +415,8 @@ public abstract class HttpReceiver
         if (HttpStatus.isInterim(exchange.getResponse().getStatus()))
             return true;
 
+        response.complete(null);
+
         // Mark atomically the response as terminated, with
         // respect to concurrency between request and response.
         terminateResponse(exchange);
@@ -542,6 +544,7 @@ public abstract class HttpReceiver
         List<Response.ResponseListener> listeners = exchange.getConversation().getResponseListeners();
         ResponseNotifier notifier = getHttpDestination().getResponseNotifier();
         notifier.notifyFailure(listeners, response, failure);
+        response.complete(failure);
 
         // We want to deliver the "complete" event as last,
         // so we emit it here only if no event handlers are
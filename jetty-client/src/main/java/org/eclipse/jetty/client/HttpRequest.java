@@ -26,11 +26,13 @@ import java.util.ArrayList;
 import java.util.Collections;
 import java.util.EnumSet;
 import java.util.HashMap;
+import java.util.HashSet;
 import java.util.Iterator;
 import java.util.List;
 import java.util.Locale;
 import java.util.Map;
 import java.util.Objects;
+import java.util.Set;
 import java.util.concurrent.ExecutionException;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
@@ -41,6 +43,7 @@ import java.util.function.Consumer;
 import java.util.function.LongConsumer;
 import java.util.function.Supplier;
 
+import org.eclipse.jetty.client.api.Connection;
 import org.eclipse.jetty.client.api.ContentProvider;
 import org.eclipse.jetty.client.api.ContentResponse;
 import org.eclipse.jetty.client.api.Request;
@@ -90,7 +93,12 @@ public class HttpRequest implements Request
     private Supplier<HttpFields> trailers;
     private String upgradeProtocol;
     private Object tag;
+    private Path unixDomainPath;
+    private List<ProxyConfiguration.Proxy> proxies;
+    private Set<ProxyConfiguration.Proxy> failedProxies;
+    private RetryPolicy retryPolicy;
     private boolean normalized;
+    private volatile Connection connection;
 
     protected HttpRequest(HttpClient client, HttpConversation conversation, URI uri)
     {
@@ -108,6 +116,7 @@ public class HttpRequest implements Request
         extractParams(query);
 
         followRedirects(client.isFollowRedirects());
+        retryPolicy(client.getRetryPolicy());
         HttpField acceptEncodingField = client.getAcceptEncodingField();
         if (acceptEncodingField != null)
             headers.put(acceptEncodingField);
@@ -133,6 +142,9 @@ public class HttpRequest implements Request
             .timeout(getTimeout(), TimeUnit.MILLISECONDS)
             .followRedirects(isFollowRedirects())
             .tag(getTag())
+            .unixDomainPath(getUnixDomainPath())
+            .proxies(getProxies())
+            .retryPolicy(getRetryPolicy())
             .headers(h -> h.clear().add(getHeaders())
                 // Remove the headers that depend on the URI.
                 .remove(EnumSet.of(
@@ -147,6 +159,28 @@ public class HttpRequest implements Request
         return newRequest;
     }
 
+    /**
+     * <p>Copies this request to send it again to the same URI.</p>
+     * <p>Differently from {@link #copy(URI)}, the copy has the given headers,
+     * the request listeners, the cookies and the attributes of this request,
+     * but no retry policy.</p>
+     *
+     * @param headers the headers of the copy
+     * @return a copy of this request
+     */
+    HttpRequest copyForRetry(HttpFields headers)
+    {
+        HttpRequest newRequest = copy(getURI());
+        newRequest.retryPolicy(null);
+        newRequest.headers(h -> h.clear().add(headers));
+        if (requestListeners != null)
+            newRequest.requestListeners = new ArrayList<>(requestListeners);
+        if (cookies != null)
+            newRequest.cookies = new ArrayList<>(cookies);
+        getAttributes().forEach(newRequest::attribute);
+        return newRequest;
+    }
+
     HttpRequest copyInstance(URI newURI)
     {
         return new HttpRequest(getHttpClient(), getConversation(), newURI);
@@ -401,6 +435,66 @@ public class HttpRequest implements Request
         return tag;
     }
 
+    @Override
+    public Request retryPolicy(RetryPolicy retryPolicy)
+    {
+        this.retryPolicy = retryPolicy;
+        return this;
+    }
+
+    @Override
+    public RetryPolicy getRetryPolicy()
+    {
+        return retryPolicy;
+    }
+
+    @Override
+    public Request unixDomainPath(Path path)
+    {
+        this.unixDomainPath = path;
+        return this;
+    }
+
+    @Override
+    public Path getUnixDomainPath()
+    {
+        return unixDomainPath;
+    }
+
+    @Override
+    public Request proxies(List<ProxyConfiguration.Proxy> proxies)
+    {
+        this.proxies = proxies == null || proxies.isEmpty() ? null : List.copyOf(proxies);
+        return this;
+    }
+
+    @Override
+    public List<ProxyConfiguration.Proxy> getProxies()
+    {
+        return proxies;
+    }
+
+    /**
+     * @return the proxies this request failed to connect to, and must not be tried again
+     */
+    Set<ProxyConfiguration.Proxy> getFailedProxies()
+    {
+        if (failedProxies == null)
+            failedProxies = new HashSet<>();
+        return failedProxies;
+    }
+
+    @Override
+    public Connection getConnection()
+    {
+        return connection;
+    }
+
+    void setConnection(Connection connection)
+    {
+        this.connection = connection;
+    }
+
     @Override
     public Request attribute(String name, Object value)
     {
@@ -710,6 +804,7 @@ public class HttpRequest implements Request
         return this;
     }
 
+    @Override
     public HttpRequest trailers(Supplier<HttpFields> trailers)
     {
         this.trailers = trailers;
@@ -895,6 +990,7 @@ public class HttpRequest implements Request
         return pushListener;
     }
 
+    @Override
     public Supplier<HttpFields> getTrailers()
     {
         return trailers;
@@ -15,6 +15,7 @@ package org.eclipse.jetty.client;
 
 import java.util.ArrayList;
 import java.util.List;
+import java.util.concurrent.CompletableFuture;
 import java.util.function.Consumer;
 
 import org.eclipse.jetty.client.api.Request;
@@ -31,6 +32,7 @@ public class HttpResponse implements Response
     private HttpVersion version;
     private int status;
     private String reason;
+    private final CompletableFuture<HttpFields> trailersFuture = new CompletableFuture<>();
     private HttpFields.Mutable trailers;
 
     public HttpResponse(Request request, List<ResponseListener> listeners)
@@ -116,11 +118,36 @@ public class HttpResponse implements Response
         return result;
     }
 
+    @Override
     public HttpFields getTrailers()
     {
         return trailers == null ? null : trailers.asImmutable();
     }
 
+    @Override
+    public CompletableFuture<HttpFields> getTrailersFuture()
+    {
+        return trailersFuture;
+    }
+
+    /**
+     * <p>Completes the {@link #getTrailersFuture() trailers future}.</p>
+     *
+     * @param failure the response failure, or {@code null} if the response succeeded
+     */
+    void complete(Throwable failure)
+    {
+        if (failure == null)
+        {
+            HttpFields trailers = getTrailers();
+            trailersFuture.complete(trailers == null ? HttpFields.EMPTY : trailers);
+        }
+        else
+        {
+            trailersFuture.completeExceptionally(failure);
+        }
+    }
+
     public HttpResponse trailer(HttpField trailer)
     {
         if (trailers == null)
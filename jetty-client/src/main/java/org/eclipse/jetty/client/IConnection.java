@@ -13,9 +13,28 @@
 
 package org.eclipse.jetty.client;
 
+import javax.net.ssl.SSLSession;
+
 import org.eclipse.jetty.client.api.Connection;
 
 public interface IConnection extends Connection
 {
     public SendFailure send(HttpExchange exchange);
+
+    /**
+     * @return the protocol spoken by this connection, such as {@code http/1.1} or {@code h2},
+     * or {@code null} if the protocol is not known
+     */
+    public default String getProtocol()
+    {
+        return null;
+    }
+
+    /**
+     * @return the TLS session of this connection, or {@code null} if this connection is not secured by TLS
+     */
+    public default SSLSession getSSLSession()
+    {
+        return null;
+    }
 }
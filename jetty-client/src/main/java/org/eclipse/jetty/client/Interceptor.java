@@ -0,0 +1,230 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.nio.ByteBuffer;
+import java.util.List;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+
+/**
+ * <p>An interceptor of requests sent by {@link HttpClient}.</p>
+ * <p>Interceptors are {@link HttpClient#addInterceptor(Interceptor) added}
+ * to {@link HttpClient} and are invoked in order for every request sent,
+ * similarly to how server-side handlers are invoked for every request
+ * received.</p>
+ * <p>An interceptor may:</p>
+ * <ul>
+ * <li>modify the request, for example adding headers, and then
+ * {@link Chain#proceed(Request, Response.Listener) proceed} with the chain</li>
+ * <li>wrap the response listener, for example to inspect or transform the
+ * response content, and then proceed with the chain with the wrapped
+ * listener; see {@link ListenerWrapper}</li>
+ * <li>not proceed with the chain, and instead {@link #respond(Request, Response.Listener, int, HttpFields, ByteBuffer) respond}
+ * with a synthetic response, for example from a cache, or
+ * {@link #fail(Request, Response.Listener, Throwable) fail} the request</li>
+ * </ul>
+ * <p>Requests that are not sent because an interceptor did not proceed with
+ * the chain do not notify the request listeners.</p>
+ * <p>Interceptors are invoked also for the requests sent by {@link HttpClient}
+ * on behalf of the application, for example when following redirects.</p>
+ */
+@FunctionalInterface
+public interface Interceptor
+{
+    /**
+     * <p>Intercepts the given request.</p>
+     * <p>Implementations must either proceed with the chain, or complete
+     * the given listener, possibly asynchronously.</p>
+     *
+     * @param request the request being sent
+     * @param listener the listener notified of the response events
+     * @param chain the chain to proceed with to send the request
+     */
+    public void intercept(Request request, Response.Listener listener, Chain chain);
+
+    /**
+     * <p>Completes the given listener with a synthetic response, without sending the request.</p>
+     *
+     * @param request the request
+     * @param listener the listener to complete
+     * @param status the response status code
+     * @param headers the response headers, or null
+     * @param content the response content, or null
+     */
+    public static void respond(Request request, Response.Listener listener, int status, HttpFields headers, ByteBuffer content)
+    {
+        HttpResponse response = new HttpResponse(request, List.of())
+            .version(HttpVersion.HTTP_1_1)
+            .status(status)
+            .reason(HttpStatus.getMessage(status));
+        if (headers != null)
+        {
+            for (HttpField field : headers)
+            {
+                response.addHeader(field);
+            }
+        }
+
+        listener.onBegin(response);
+        for (HttpField field : response.getHeaders())
+        {
+            listener.onHeader(response, field);
+        }
+        listener.onHeaders(response);
+
+        Runnable complete = () ->
+        {
+            listener.onSuccess(response);
+            listener.onComplete(new Result(request, response));
+            response.complete(null);
+        };
+        if (BufferUtil.isEmpty(content))
+        {
+            listener.onBeforeContent(response, demand -> {});
+            complete.run();
+        }
+        else
+        {
+            AtomicBoolean demanded = new AtomicBoolean();
+            LongConsumer demand = value ->
+            {
+                // The content is only one chunk, so subsequent demands are ignored.
+                if (demanded.compareAndSet(false, true))
+                    listener.onContent(response, d -> {}, content.slice(), Callback.from(complete, x -> fail(request, response, listener, x)));
+            };
+            listener.onBeforeContent(response, demand);
+        }
+    }
+
+    /**
+     * <p>Completes the given listener with the given failure, without sending the request.</p>
+     *
+     * @param request the request
+     * @param listener the listener to complete
+     * @param failure the failure
+     */
+    public static void fail(Request request, Response.Listener listener, Throwable failure)
+    {
+        fail(request, new HttpResponse(request, List.of()), listener, failure);
+    }
+
+    private static void fail(Request request, HttpResponse response, Response.Listener listener, Throwable failure)
+    {
+        listener.onFailure(response, failure);
+        listener.onComplete(new Result(request, failure, response, failure));
+        response.complete(failure);
+    }
+
+    /**
+     * <p>The chain of interceptors.</p>
+     */
+    public interface Chain
+    {
+        /**
+         * <p>Proceeds with the next interceptor, or sends the request if there
+         * are no more interceptors.</p>
+         *
+         * @param request the request to send, typically the same request passed to the interceptor
+         * @param listener the listener notified of the response events, possibly a wrapper
+         * of the listener passed to the interceptor
+         */
+        public void proceed(Request request, Response.Listener listener);
+    }
+
+    /**
+     * <p>A {@link Response.Listener} that forwards the response events to another listener.</p>
+     * <p>Subclasses may override methods to inspect or transform the response,
+     * for example overriding {@link #onContent(Response, LongConsumer, ByteBuffer, Callback)}
+     * to transform the response content.</p>
+     */
+    public static class ListenerWrapper implements Response.Listener
+    {
+        private final Response.Listener wrapped;
+
+        public ListenerWrapper(Response.Listener wrapped)
+        {
+            this.wrapped = wrapped;
+        }
+
+        public Response.Listener getWrapped()
+        {
+            return wrapped;
+        }
+
+        @Override
+        public void onBegin(Response response)
+        {
+            wrapped.onBegin(response);
+        }
+
+        @Override
+        public boolean onHeader(Response response, HttpField field)
+        {
+            return wrapped.onHeader(response, field);
+        }
+
+        @Override
+        public void onHeaders(Response response)
+        {
+            wrapped.onHeaders(response);
+        }
+
+        @Override
+        public void onBeforeContent(Response response, LongConsumer demand)
+        {
+            wrapped.onBeforeContent(response, demand);
+        }
+
+        @Override
+        public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+        {
+            wrapped.onContent(response, demand, content, callback);
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            wrapped.onSuccess(response);
+        }
+
+        @Override
+        public void onFailure(Response response, Throwable failure)
+        {
+            wrapped.onFailure(response, failure);
+        }
+
+        @Override
+        public void onComplete(Result result)
+        {
+            wrapped.onComplete(result);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), wrapped);
+        }
+    }
+}
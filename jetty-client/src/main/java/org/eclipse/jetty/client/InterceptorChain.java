@@ -0,0 +1,214 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.Iterator;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.function.BiConsumer;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.MathUtils;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Invokes the {@link Interceptor}s in order, and eventually sends the request.</p>
+ * <p>The response listeners of the request are exposed to interceptors as a single
+ * {@link Response.Listener}; if no interceptor wraps it, the original response
+ * listeners are used to send the request.</p>
+ */
+class InterceptorChain implements Interceptor.Chain
+{
+    private static final Logger LOG = LoggerFactory.getLogger(InterceptorChain.class);
+
+    private final List<Interceptor> interceptors;
+    private final BiConsumer<HttpRequest, List<Response.ResponseListener>> sender;
+    private final List<Response.ResponseListener> listeners;
+    private final Listeners listener;
+    private int index;
+
+    InterceptorChain(List<Interceptor> interceptors, BiConsumer<HttpRequest, List<Response.ResponseListener>> sender, List<Response.ResponseListener> listeners)
+    {
+        this.interceptors = interceptors;
+        this.sender = sender;
+        this.listeners = listeners;
+        this.listener = new Listeners(listeners);
+    }
+
+    void start(HttpRequest request)
+    {
+        proceed(request, listener);
+    }
+
+    @Override
+    public void proceed(Request request, Response.Listener responseListener)
+    {
+        if (!(request instanceof HttpRequest))
+            throw new IllegalArgumentException("Invalid request " + request);
+        HttpRequest httpRequest = (HttpRequest)request;
+
+        int position = index++;
+        if (position < interceptors.size())
+        {
+            Interceptor interceptor = interceptors.get(position);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Intercepting {} with {}", request, interceptor);
+            try
+            {
+                interceptor.intercept(request, responseListener, this);
+            }
+            catch (Throwable x)
+            {
+                // Fail the request only if it did not proceed,
+                // otherwise the response listener is completed twice.
+                if (index == position + 1)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Failure from {} intercepting {}", interceptor, request, x);
+                    Interceptor.fail(request, responseListener, x);
+                }
+                else
+                {
+                    LOG.warn("Failure from {} after proceeding with {}", interceptor, request, x);
+                }
+            }
+        }
+        else
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Sending {} with {}", request, responseListener);
+            // Avoid the indirection of the composite listener if it has not been wrapped.
+            List<Response.ResponseListener> responseListeners = responseListener == listener ? listeners : List.of(responseListener);
+            sender.accept(httpRequest, responseListeners);
+        }
+    }
+
+    /**
+     * <p>A {@link Response.Listener} that notifies a list of response listeners.</p>
+     */
+    static class Listeners implements Response.Listener
+    {
+        private final ResponseNotifier notifier = new ResponseNotifier();
+        private final Map<Object, Long> demands = new ConcurrentHashMap<>();
+        private final List<Response.ResponseListener> listeners;
+        private final List<Response.DemandedContentListener> contentListeners = new ArrayList<>(2);
+
+        Listeners(List<Response.ResponseListener> listeners)
+        {
+            this.listeners = listeners;
+            for (Response.ResponseListener listener : listeners)
+            {
+                if (listener instanceof Response.DemandedContentListener)
+                    contentListeners.add((Response.DemandedContentListener)listener);
+            }
+        }
+
+        @Override
+        public void onBegin(Response response)
+        {
+            notifier.notifyBegin(listeners, response);
+        }
+
+        @Override
+        public boolean onHeader(Response response, HttpField field)
+        {
+            return notifier.notifyHeader(listeners, response, field);
+        }
+
+        @Override
+        public void onHeaders(Response response)
+        {
+            notifier.notifyHeaders(listeners, response);
+        }
+
+        @Override
+        public void onBeforeContent(Response response, LongConsumer demand)
+        {
+            demands.clear();
+            if (contentListeners.isEmpty())
+                demand.accept(1);
+            else
+                notifier.notifyBeforeContent(response, (context, value) -> demand(demand, context, value), contentListeners);
+        }
+
+        @Override
+        public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+        {
+            notifier.notifyContent(response, (context, value) -> demand(demand, context, value), content, callback, contentListeners);
+        }
+
+        private void demand(LongConsumer demand, Object context, long value)
+        {
+            if (contentListeners.size() <= 1)
+            {
+                demand.accept(value);
+                return;
+            }
+
+            // Demand only when all the content listeners have demanded.
+            demands.merge(context, value, MathUtils::cappedAdd);
+            if (demands.size() == contentListeners.size())
+            {
+                long minDemand = demands.values().stream().mapToLong(Long::longValue).min().orElse(0);
+                if (minDemand > 0)
+                {
+                    Iterator<Map.Entry<Object, Long>> iterator = demands.entrySet().iterator();
+                    while (iterator.hasNext())
+                    {
+                        Map.Entry<Object, Long> entry = iterator.next();
+                        long newValue = entry.getValue() - minDemand;
+                        if (newValue == 0)
+                            iterator.remove();
+                        else
+                            entry.setValue(newValue);
+                    }
+                    demand.accept(minDemand);
+                }
+            }
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            notifier.notifySuccess(listeners, response);
+        }
+
+        @Override
+        public void onFailure(Response response, Throwable failure)
+        {
+            notifier.notifyFailure(listeners, response, failure);
+        }
+
+        @Override
+        public void onComplete(Result result)
+        {
+            notifier.notifyComplete(listeners, result);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), listeners);
+        }
+    }
+}
@@ -0,0 +1,82 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.util.ArrayList;
+import java.util.List;
+
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.Pool;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+
+/**
+ * <p>A {@link ConnectionPool} that provides the connection
+ * with the fewest active requests among the ones that are available.</p>
+ * <p>This pool is typically used with multiplexed protocols such as HTTP/2,
+ * where requests are spread evenly over the connections, rather than filling
+ * the first connection up to its max multiplex before using the next one.</p>
+ * <p>Among connections with the same number of active requests,
+ * the connection opened first is preferred.</p>
+ *
+ * @see RoundRobinConnectionPool
+ */
+@ManagedObject
+public class LeastLoadedConnectionPool extends MultiplexConnectionPool
+{
+    public LeastLoadedConnectionPool(HttpDestination destination, int maxConnections, Callback requester, int maxMultiplex)
+    {
+        super(destination, Pool.StrategyType.FIRST, maxConnections, false, requester, maxMultiplex);
+    }
+
+    @Override
+    protected Pool<Connection>.Entry acquireEntry(Pool<Connection> pool)
+    {
+        // Snapshot the load, as it may change while sorting.
+        List<Load> loads = new ArrayList<>();
+        for (Pool<Connection>.Entry entry : pool.values())
+        {
+            if (!entry.isReserved() && !entry.isClosed())
+                loads.add(new Load(entry));
+        }
+        loads.sort(null);
+
+        // The least loaded entry may have been acquired
+        // concurrently up to its max multiplex, try the next.
+        for (Load load : loads)
+        {
+            if (pool.acquire(load.entry))
+                return load.entry;
+        }
+        return null;
+    }
+
+    private static class Load implements Comparable<Load>
+    {
+        private final Pool<Connection>.Entry entry;
+        private final int count;
+
+        private Load(Pool<Connection>.Entry entry)
+        {
+            this.entry = entry;
+            this.count = entry.getMultiplexCount();
+        }
+
+        @Override
+        public int compareTo(Load that)
+        {
+            return Integer.compare(count, that.count);
+        }
+    }
+}
@@ -15,6 +15,7 @@ package org.eclipse.jetty.client;
 
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
+import java.nio.file.Path;
 import java.util.List;
 import java.util.Map;
 import java.util.Objects;
@@ -39,6 +40,14 @@ import org.eclipse.jetty.util.URIUtil;
  * to the destination {@code tag}, so that all the connections to the server
  * associated to that destination can specify the PROXY protocol bytes for
  * that particular client connection.</p>
+ * <p>Finally, an origin may specify a Unix-Domain path, so that connections
+ * to the destination are established via Unix-Domain sockets rather than
+ * via TCP, while the {@code scheme}, {@code host} and {@code port} are still
+ * used to build the request URI.</p>
+ * <p>An origin may also specify the proxy to reach it, when the proxy differs
+ * from the one that the {@link ProxyConfiguration} would match, for example
+ * when the request specifies its own proxies or when failing over to the
+ * next proxy.</p>
  */
 public class Origin
 {
@@ -46,6 +55,8 @@ public class Origin
     private final Address address;
     private final Object tag;
     private final Protocol protocol;
+    private final Path unixDomainPath;
+    private final ProxyConfiguration.Proxy proxy;
 
     public Origin(String scheme, String host, int port)
     {
@@ -73,11 +84,23 @@ public class Origin
     }
 
     public Origin(String scheme, Address address, Object tag, Protocol protocol)
+    {
+        this(scheme, address, tag, protocol, null);
+    }
+
+    public Origin(String scheme, Address address, Object tag, Protocol protocol, Path unixDomainPath)
+    {
+        this(scheme, address, tag, protocol, unixDomainPath, null);
+    }
+
+    public Origin(String scheme, Address address, Object tag, Protocol protocol, Path unixDomainPath, ProxyConfiguration.Proxy proxy)
     {
         this.scheme = Objects.requireNonNull(scheme);
         this.address = address;
         this.tag = tag;
         this.protocol = protocol;
+        this.unixDomainPath = unixDomainPath;
+        this.proxy = proxy;
     }
 
     public String getScheme()
@@ -100,6 +123,22 @@ public class Origin
         return protocol;
     }
 
+    /**
+     * @return the Unix-Domain path to connect to, or null to connect via TCP
+     */
+    public Path getUnixDomainPath()
+    {
+        return unixDomainPath;
+    }
+
+    /**
+     * @return the proxy to reach this origin, or null to use the {@link ProxyConfiguration}
+     */
+    public ProxyConfiguration.Proxy getProxy()
+    {
+        return proxy;
+    }
+
     @Override
     public boolean equals(Object obj)
     {
@@ -111,13 +150,15 @@ public class Origin
         return scheme.equals(that.scheme) &&
             address.equals(that.address) &&
             Objects.equals(tag, that.tag) &&
-            Objects.equals(protocol, that.protocol);
+            Objects.equals(protocol, that.protocol) &&
+            Objects.equals(unixDomainPath, that.unixDomainPath) &&
+            Objects.equals(proxy, that.proxy);
     }
 
     @Override
     public int hashCode()
     {
-        return Objects.hash(scheme, address, tag, protocol);
+        return Objects.hash(scheme, address, tag, protocol, unixDomainPath, proxy);
     }
 
     public String asString()
@@ -130,12 +171,14 @@ public class Origin
     @Override
     public String toString()
     {
-        return String.format("%s@%x[%s,tag=%s,protocol=%s]",
+        return String.format("%s@%x[%s,tag=%s,protocol=%s,unixDomainPath=%s,proxy=%s]",
             getClass().getSimpleName(),
             hashCode(),
             asString(),
             getTag(),
-            getProtocol());
+            getProtocol(),
+            getUnixDomainPath(),
+            getProxy());
     }
 
     public static class Address
@@ -14,16 +14,26 @@
 package org.eclipse.jetty.client;
 
 import java.net.URI;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.EventListener;
 import java.util.HashSet;
 import java.util.List;
+import java.util.Map;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
 
 import org.eclipse.jetty.http.HttpScheme;
 import org.eclipse.jetty.io.ClientConnectionFactory;
 import org.eclipse.jetty.util.BlockingArrayQueue;
 import org.eclipse.jetty.util.HostPort;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
 
 /**
  * The configuration of the forward proxy to use with {@link org.eclipse.jetty.client.HttpClient}.
@@ -33,12 +43,30 @@ import org.eclipse.jetty.util.ssl.SslContextFactory;
  * ProxyConfiguration proxyConfig = httpClient.getProxyConfiguration();
  * proxyConfig.addProxy(new HttpProxy(proxyHost, 8080));
  * </pre>
+ * <p>When more than one proxy matches an origin, the proxies are tried in the
+ * order they have been added: if a connection to a proxy cannot be established,
+ * the proxy is considered unhealthy for the {@link #getUnhealthyPeriod() unhealthy period},
+ * the requests queued for that proxy fail over to the next matching proxy, and
+ * new requests skip the unhealthy proxy until the period expires or a connection
+ * to it succeeds again.
+ * Each proxy has its own origin, so the proxy credentials stored in the
+ * {@link HttpClient#getAuthenticationStore() authentication store} are used
+ * independently for each proxy the requests fail over to.</p>
+ * <p>Requests may override the proxies in this configuration via
+ * {@link org.eclipse.jetty.client.api.Request#proxies(List)}.</p>
+ * <p>Applications may be notified of the proxy selection decisions via
+ * {@link #addListener(Listener) listeners}.</p>
  *
  * @see HttpClient#getProxyConfiguration()
  */
 public class ProxyConfiguration
 {
+    private static final Logger LOG = LoggerFactory.getLogger(ProxyConfiguration.class);
+
     private final List<Proxy> proxies = new BlockingArrayQueue<>();
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
+    private final Map<Proxy, Long> unhealthy = new ConcurrentHashMap<>();
+    private long unhealthyPeriod = 30000;
 
     /**
      * <p>Returns the list of proxies added to this configuration.</p>
@@ -80,16 +108,275 @@ public class ProxyConfiguration
         return proxies.remove(proxy);
     }
 
+    /**
+     * @param listener the listener to notify of proxy selection decisions
+     */
+    public void addListener(Listener listener)
+    {
+        listeners.add(Objects.requireNonNull(listener));
+    }
+
+    /**
+     * @param listener the listener to remove
+     * @return true if the listener was removed
+     */
+    public boolean removeListener(Listener listener)
+    {
+        return listeners.remove(listener);
+    }
+
+    /**
+     * @return the time in milliseconds a proxy that failed to connect is skipped
+     */
+    public long getUnhealthyPeriod()
+    {
+        return unhealthyPeriod;
+    }
+
+    /**
+     * @param unhealthyPeriod the time in milliseconds a proxy that failed to connect is skipped
+     */
+    public void setUnhealthyPeriod(long unhealthyPeriod)
+    {
+        this.unhealthyPeriod = unhealthyPeriod;
+    }
+
+    /**
+     * <p>Returns the first proxy that matches the given origin,
+     * regardless of its health.</p>
+     *
+     * @param origin the origin to match
+     * @return the first matching proxy, or null if the origin is not proxied
+     * @see #select(Origin, List)
+     */
     public Proxy match(Origin origin)
     {
+        Proxy proxy = origin.getProxy();
+        if (proxy != null)
+            return proxy;
+        for (Proxy p : proxies)
+        {
+            if (p.matches(origin))
+                return p;
+        }
+        return null;
+    }
+
+    /**
+     * <p>Selects the proxy to use to reach the given origin.</p>
+     * <p>The candidate proxies are either the given ones, typically specified
+     * by a request, or the proxies of this configuration that match the origin.
+     * The first healthy candidate is selected; if no candidate is healthy, the
+     * first candidate is selected anyway, so that requests are not failed
+     * without at least attempting a connection.</p>
+     *
+     * @param origin the origin to reach
+     * @param candidates the proxies to select from, or null to select from this configuration
+     * @return the selected proxy, or null if the origin is not proxied
+     */
+    public Proxy select(Origin origin, List<Proxy> candidates)
+    {
+        List<Proxy> proxies = candidates(origin, candidates);
+        Proxy proxy = select(origin, proxies, Set.of());
+        if (proxy == null && !proxies.isEmpty())
+        {
+            proxy = proxies.get(0);
+            notifySelected(origin, proxy);
+        }
+        return proxy;
+    }
+
+    /**
+     * <p>Selects the proxy to fail over to, after connections to the proxies
+     * in {@code failed} could not be established.</p>
+     *
+     * @param origin the origin to reach
+     * @param candidates the proxies to select from, or null to select from this configuration
+     * @param failed the proxies already tried
+     * @return the next healthy proxy to try, or null if there is none
+     */
+    public Proxy failover(Origin origin, List<Proxy> candidates, Collection<Proxy> failed)
+    {
+        return select(origin, candidates(origin, candidates), failed);
+    }
+
+    private List<Proxy> candidates(Origin origin, List<Proxy> candidates)
+    {
+        if (candidates != null)
+            return candidates;
+        List<Proxy> result = new ArrayList<>();
         for (Proxy proxy : proxies)
         {
             if (proxy.matches(origin))
+                result.add(proxy);
+        }
+        return result;
+    }
+
+    private Proxy select(Origin origin, List<Proxy> candidates, Collection<Proxy> excluded)
+    {
+        for (Proxy proxy : candidates)
+        {
+            if (excluded.contains(proxy))
+                continue;
+            if (isHealthy(proxy))
+            {
+                notifySelected(origin, proxy);
                 return proxy;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Skipping unhealthy {} for {}", proxy, origin);
+            notifySkipped(origin, proxy);
         }
         return null;
     }
 
+    /**
+     * @param proxy the proxy to test
+     * @return whether the proxy is healthy, that is it did not recently fail to connect
+     */
+    public boolean isHealthy(Proxy proxy)
+    {
+        Long expireNanoTime = unhealthy.get(proxy);
+        return expireNanoTime == null || NanoTime.isBeforeOrSame(expireNanoTime, NanoTime.now());
+    }
+
+    /**
+     * <p>Records that a connection to the given proxy was established,
+     * marking the proxy as healthy.</p>
+     *
+     * @param proxy the proxy
+     */
+    public void succeeded(Proxy proxy)
+    {
+        if (unhealthy.remove(proxy) != null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Recovered {}", proxy);
+            notifyRecovered(proxy);
+        }
+    }
+
+    /**
+     * <p>Records that a connection to the given proxy could not be established,
+     * marking the proxy as unhealthy for the {@link #getUnhealthyPeriod() unhealthy period}.</p>
+     *
+     * @param proxy the proxy
+     * @param failure the connection failure
+     */
+    public void failed(Proxy proxy, Throwable failure)
+    {
+        unhealthy.put(proxy, NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(getUnhealthyPeriod()));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Unhealthy {}", proxy, failure);
+        notifyFailed(proxy, failure);
+    }
+
+    private void notifySelected(Origin origin, Proxy proxy)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onSelected(origin, proxy);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Exception while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifySkipped(Origin origin, Proxy proxy)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onSkipped(origin, proxy);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Exception while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyFailed(Proxy proxy, Throwable failure)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onFailed(proxy, failure);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Exception while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyRecovered(Proxy proxy)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onRecovered(proxy);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Exception while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    /**
+     * <p>Listener for proxy selection decisions and proxy health changes.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when a proxy is selected to reach an origin.</p>
+         *
+         * @param origin the origin to reach
+         * @param proxy the selected proxy
+         */
+        default void onSelected(Origin origin, Proxy proxy)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when an unhealthy proxy is skipped.</p>
+         *
+         * @param origin the origin to reach
+         * @param proxy the skipped proxy
+         */
+        default void onSkipped(Origin origin, Proxy proxy)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a connection to a proxy could not be established.</p>
+         *
+         * @param proxy the proxy that is now unhealthy
+         * @param failure the connection failure
+         */
+        default void onFailed(Proxy proxy, Throwable failure)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a connection to an unhealthy proxy succeeds.</p>
+         *
+         * @param proxy the proxy that is now healthy
+         */
+        default void onRecovered(Proxy proxy)
+        {
+        }
+    }
+
     public abstract static class Proxy
     {
         // TODO use InetAddressSet? Or IncludeExcludeSet?
@@ -391,8 +391,80 @@ public abstract class ProxyProtocolClientConnectionFactory implements ClientConn
                 UNSPEC, STREAM, DGRAM
             }
 
+            /**
+             * <p>A Type-Length-Value vector, see section 2.2.7 of the PROXY protocol specification.</p>
+             * <p>TLV types in the range {@code 0xE0-0xEF} are reserved for application-specific data.</p>
+             */
             public static class TLV
             {
+                public static final int PP2_TYPE_ALPN = 0x01;
+                public static final int PP2_TYPE_AUTHORITY = 0x02;
+                public static final int PP2_TYPE_UNIQUE_ID = 0x05;
+                public static final int PP2_TYPE_SSL = 0x20;
+                public static final int PP2_SUBTYPE_SSL_VERSION = 0x21;
+                public static final int PP2_SUBTYPE_SSL_CN = 0x22;
+                public static final int PP2_SUBTYPE_SSL_CIPHER = 0x23;
+                public static final int PP2_SUBTYPE_SSL_SIG_ALG = 0x24;
+                public static final int PP2_SUBTYPE_SSL_KEY_ALG = 0x25;
+                public static final int PP2_CLIENT_SSL = 0x01;
+                public static final int PP2_CLIENT_CERT_CONN = 0x02;
+                public static final int PP2_CLIENT_CERT_SESS = 0x04;
+
+                /**
+                 * @param protocol the application protocol, for example {@code h2}
+                 * @return a new {@link #PP2_TYPE_ALPN} TLV
+                 */
+                public static TLV alpn(String protocol)
+                {
+                    return new TLV(PP2_TYPE_ALPN, protocol.getBytes(StandardCharsets.US_ASCII));
+                }
+
+                /**
+                 * @param authority the host name, typically the TLS SNI host name
+                 * @return a new {@link #PP2_TYPE_AUTHORITY} TLV
+                 */
+                public static TLV authority(String authority)
+                {
+                    return new TLV(PP2_TYPE_AUTHORITY, authority.getBytes(StandardCharsets.UTF_8));
+                }
+
+                /**
+                 * @param uniqueId the opaque connection identifier, at most 128 bytes
+                 * @return a new {@link #PP2_TYPE_UNIQUE_ID} TLV
+                 */
+                public static TLV uniqueId(byte[] uniqueId)
+                {
+                    if (uniqueId.length > 128)
+                        throw new IllegalArgumentException("Invalid unique ID length: " + uniqueId.length);
+                    return new TLV(PP2_TYPE_UNIQUE_ID, uniqueId);
+                }
+
+                /**
+                 * @param client the bitmask of {@link #PP2_CLIENT_SSL}, {@link #PP2_CLIENT_CERT_CONN}
+                 * and {@link #PP2_CLIENT_CERT_SESS}
+                 * @param verify the client certificate verification result, 0 if successfully verified
+                 * @param subTLVs the sub-TLVs, for example of type {@link #PP2_SUBTYPE_SSL_VERSION}
+                 * @return a new {@link #PP2_TYPE_SSL} TLV
+                 */
+                public static TLV ssl(int client, int verify, List<TLV> subTLVs)
+                {
+                    int length = 1 + 4;
+                    for (TLV subTLV : subTLVs)
+                    {
+                        length += 1 + 2 + subTLV.getValue().length;
+                    }
+                    ByteBuffer buffer = ByteBuffer.allocate(length);
+                    buffer.put((byte)client);
+                    buffer.putInt(verify);
+                    for (TLV subTLV : subTLVs)
+                    {
+                        buffer.put((byte)subTLV.getType());
+                        buffer.putShort((short)subTLV.getValue().length);
+                        buffer.put(subTLV.getValue());
+                    }
+                    return new TLV(PP2_TYPE_SSL, buffer.array());
+                }
+
                 private final int type;
                 private final byte[] value;
 
@@ -199,6 +199,12 @@ public class ResponseNotifier
 
     public void notifyComplete(List<Response.ResponseListener> listeners, Result result)
     {
+        // Make sure the trailers future of the response is completed,
+        // also when it failed without being received (for example, due
+        // to a request failure).
+        Response response = result.getResponse();
+        if (response instanceof HttpResponse)
+            ((HttpResponse)response).complete(result.getFailure());
         for (Response.ResponseListener listener : listeners)
         {
             if (listener instanceof Response.CompleteListener)
@@ -0,0 +1,341 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.util.Objects;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.TimeUnit;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IO;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Downloads a resource to a file, resuming the download with {@code Range}
+ * requests if the transfer is interrupted.</p>
+ * <p>The download is resumed from the length of the file, with an {@code If-Range}
+ * header carrying the strong {@code ETag} of the resource, so that if the resource
+ * changed in the meantime the server responds with the whole new content, that
+ * replaces the partial content of the file.
+ * If the resource has no strong {@code ETag}, the download restarts from the
+ * beginning every time.
+ * The {@code ETag} can be {@link #setETag(String) set} to resume a download
+ * that was started by another instance, for example before a restart.</p>
+ * <p>The download is resumed, after the delays computed by the {@link RetryPolicy},
+ * when it fails with a {@link RetryPolicy#isRetryable(Throwable) retryable} failure,
+ * up to the {@link RetryPolicy#getMaxAttempts() max attempts} of the retry policy.
+ * Every request is also subject to the retry policy itself, so that failures
+ * that happen before a response is received are retried as usual.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * ResumableDownload download = new ResumableDownload(httpClient, uri, path);
+ * download.setRequestCustomizer(request -> request.timeout(1, TimeUnit.HOURS));
+ * Path file = download.start().get();
+ * }</pre>
+ * <p>Instances of this class are not reusable, so one must be allocated for each download.</p>
+ */
+public class ResumableDownload
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResumableDownload.class);
+
+    private final CompletableFuture<Path> promise = new CompletableFuture<>();
+    private final HttpClient client;
+    private final URI uri;
+    private final Path path;
+    private RetryPolicy retryPolicy;
+    private Consumer<Request> requestCustomizer;
+    private volatile String etag;
+    private volatile int attempts;
+    private volatile int resumes;
+
+    public ResumableDownload(HttpClient client, URI uri, Path path)
+    {
+        this.client = Objects.requireNonNull(client);
+        this.uri = Objects.requireNonNull(uri);
+        this.path = Objects.requireNonNull(path);
+        this.retryPolicy = client.getRetryPolicy();
+    }
+
+    public URI getURI()
+    {
+        return uri;
+    }
+
+    public Path getPath()
+    {
+        return path;
+    }
+
+    /**
+     * @return the strong {@code ETag} of the resource, or null if not known yet
+     */
+    public String getETag()
+    {
+        return etag;
+    }
+
+    /**
+     * @param etag the strong {@code ETag} of the resource whose partial content is in the file
+     */
+    public void setETag(String etag)
+    {
+        this.etag = etag;
+    }
+
+    /**
+     * @return the retry policy that determines when to resume the download
+     */
+    public RetryPolicy getRetryPolicy()
+    {
+        return retryPolicy;
+    }
+
+    /**
+     * @param retryPolicy the retry policy that determines when to resume the download,
+     * or null to never resume (in which case a partial file is still resumed when started)
+     */
+    public void setRetryPolicy(RetryPolicy retryPolicy)
+    {
+        this.retryPolicy = retryPolicy;
+    }
+
+    /**
+     * @param requestCustomizer a function invoked with every request, for example to add headers or listeners
+     */
+    public void setRequestCustomizer(Consumer<Request> requestCustomizer)
+    {
+        this.requestCustomizer = requestCustomizer;
+    }
+
+    /**
+     * @return the number of requests sent
+     */
+    public int getAttempts()
+    {
+        return attempts;
+    }
+
+    /**
+     * @return the number of requests that resumed the download from a non-zero offset
+     */
+    public int getResumes()
+    {
+        return resumes;
+    }
+
+    /**
+     * <p>Starts the download, resuming from the content already present in the file, if any.</p>
+     *
+     * @return a future completed with the file path when the whole content has been downloaded
+     */
+    public CompletableFuture<Path> start()
+    {
+        attempt();
+        return promise;
+    }
+
+    private void attempt()
+    {
+        try
+        {
+            String validator = getETag();
+            long offset = validator != null && Files.exists(path) ? Files.size(path) : 0;
+
+            Request request = client.newRequest(uri).retryPolicy(getRetryPolicy());
+            if (requestCustomizer != null)
+                requestCustomizer.accept(request);
+            // Ranges apply to the encoded content, so the
+            // content must not be encoded to be resumable.
+            request.headers(headers -> headers.put(HttpHeader.ACCEPT_ENCODING, "identity"));
+            if (offset > 0)
+            {
+                request.headers(headers -> headers
+                    .put(HttpHeader.RANGE, "bytes=" + offset + "-")
+                    .put(HttpHeader.IF_RANGE, validator));
+                ++resumes;
+            }
+            int attempt = ++attempts;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Download attempt #{} of {} from offset {}", attempt, uri, offset);
+            request.send(new AttemptListener(attempt, offset));
+        }
+        catch (Throwable x)
+        {
+            promise.completeExceptionally(x);
+        }
+    }
+
+    private void resume(int attempt, Throwable failure)
+    {
+        RetryPolicy policy = getRetryPolicy();
+        if (promise.isDone() || policy == null || attempt >= policy.getMaxAttempts() || !policy.isRetryable(failure))
+        {
+            promise.completeExceptionally(failure);
+            return;
+        }
+        long delay = policy.computeDelay(attempt);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Resuming download of {} in {} ms", uri, delay, failure);
+        client.getScheduler().schedule(this::attempt, delay, TimeUnit.MILLISECONDS);
+    }
+
+    /**
+     * <p>Aborts the download, leaving the partial content in the file.</p>
+     *
+     * @param failure the cause of the abort
+     * @return whether the download was aborted
+     */
+    public boolean abort(Throwable failure)
+    {
+        return promise.completeExceptionally(failure);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s->%s,attempts=%d]", getClass().getSimpleName(), hashCode(), uri, path, getAttempts());
+    }
+
+    private static String strongETag(Response response)
+    {
+        String value = response.getHeaders().get(HttpHeader.ETAG);
+        if (value == null || value.startsWith("W/"))
+            return null;
+        return value;
+    }
+
+    private class AttemptListener implements Response.Listener
+    {
+        private final int attempt;
+        private final long offset;
+        private FileChannel channel;
+        private boolean complete;
+
+        private AttemptListener(int attempt, long offset)
+        {
+            this.attempt = attempt;
+            this.offset = offset;
+        }
+
+        @Override
+        public void onHeaders(Response response)
+        {
+            try
+            {
+                int status = response.getStatus();
+                if (status == HttpStatus.PARTIAL_CONTENT_206 && offset > 0)
+                {
+                    String contentRange = response.getHeaders().get(HttpHeader.CONTENT_RANGE);
+                    String etag = strongETag(response);
+                    if (contentRange == null || !contentRange.startsWith("bytes " + offset + "-") ||
+                        (etag != null && !etag.equals(getETag())))
+                        throw new HttpResponseException("Invalid partial response " + contentRange, response);
+                    channel = FileChannel.open(path, StandardOpenOption.WRITE);
+                    channel.truncate(offset);
+                    channel.position(offset);
+                }
+                else if (status == HttpStatus.OK_200)
+                {
+                    // The resource changed, or the range was
+                    // ignored by the server: start from scratch.
+                    setETag(strongETag(response));
+                    channel = FileChannel.open(path, StandardOpenOption.CREATE, StandardOpenOption.WRITE, StandardOpenOption.TRUNCATE_EXISTING);
+                }
+                else if (status == HttpStatus.RANGE_NOT_SATISFIABLE_416 && offset > 0 &&
+                    ("bytes */" + offset).equals(response.getHeaders().get(HttpHeader.CONTENT_RANGE)))
+                {
+                    // The file already contains the whole content.
+                    complete = true;
+                }
+                else
+                {
+                    throw new HttpResponseException("Unexpected response " + status, response);
+                }
+            }
+            catch (Throwable x)
+            {
+                response.abort(x);
+            }
+        }
+
+        @Override
+        public void onContent(Response response, ByteBuffer content, Callback callback)
+        {
+            try
+            {
+                if (promise.isDone())
+                    throw new IOException("Download aborted");
+                if (channel != null)
+                {
+                    while (content.hasRemaining())
+                    {
+                        channel.write(content);
+                    }
+                }
+                callback.succeeded();
+            }
+            catch (Throwable x)
+            {
+                callback.failed(x);
+                response.abort(x);
+            }
+        }
+
+        @Override
+        public void onComplete(Result result)
+        {
+            try
+            {
+                if (channel != null)
+                    channel.force(false);
+            }
+            catch (IOException x)
+            {
+                if (!result.isFailed())
+                    result = new Result(result, x);
+            }
+            IO.close(channel);
+
+            Throwable failure = result.getFailure();
+            if (failure == null)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Downloaded {} to {}{}", uri, path, complete ? " (already complete)" : "");
+                promise.complete(path);
+            }
+            else if (failure instanceof HttpResponseException)
+            {
+                promise.completeExceptionally(failure);
+            }
+            else
+            {
+                resume(attempt, failure);
+            }
+        }
+    }
+}
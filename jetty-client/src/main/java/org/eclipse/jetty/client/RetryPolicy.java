@@ -0,0 +1,771 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.net.UnknownHostException;
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.EventListener;
+import java.util.List;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.BiConsumer;
+import java.util.function.LongConsumer;
+import javax.net.ssl.SSLHandshakeException;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.DateParser;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A policy to automatically retry requests that failed or that received
+ * a response with a retryable status code, such as {@code 503}.</p>
+ * <p>A retry policy may be configured on {@link HttpClient#setRetryPolicy(RetryPolicy) HttpClient},
+ * so that it applies to all requests, or on individual requests via
+ * {@link Request#retryPolicy(RetryPolicy)}.</p>
+ * <p>A request is retried only if:</p>
+ * <ul>
+ * <li>its method is idempotent, such as {@code GET} or {@code PUT}, unless
+ * {@link #setRetryNonIdempotent(boolean)} is true, and</li>
+ * <li>its content, if any, is {@link Request.Content#isReproducible() reproducible},
+ * so that it can be sent again, and</li>
+ * <li>it failed before a response was received, with a failure that
+ * {@link #isRetryable(Throwable) is retryable}, or it received a response
+ * with one of the {@link #getRetryableStatuses() retryable statuses}, and</li>
+ * <li>it has not been aborted, and its total {@link Request#timeout(long, TimeUnit) timeout}
+ * does not expire before the retry, and</li>
+ * <li>the {@link #getMaxAttempts() max number of attempts} is not reached, and
+ * the retry budget is not exhausted.</li>
+ * </ul>
+ * <p>Retries are delayed with an exponential backoff, starting from
+ * {@link #getInitialDelay()} and multiplied by {@link #getMultiplier()} at every
+ * attempt, up to {@link #getMaxDelay()}; a random {@link #getJitter() jitter}
+ * is subtracted from the delay, so that clients do not retry in lockstep.
+ * The {@code Retry-After} response header, if present, is honored unless it
+ * exceeds {@link #getMaxRetryAfter()}, in which case the response is not retried.</p>
+ * <p>The retry budget limits the number of retries to a {@link #getBudgetRatio() ratio}
+ * of the requests sent, so that retries do not overload a server that is already
+ * struggling; every request deposits the ratio in the budget, up to
+ * {@link #getBudgetCapacity()} retries, and every retry withdraws one.</p>
+ * <p>The response listeners of the request are notified only of the events of
+ * the last attempt; the request listeners are notified for every attempt.
+ * Every attempt is a copy of the original request with the same headers, that
+ * is also processed by the {@link Interceptor}s of {@link HttpClient}.
+ * The attempts are notified to the {@link Listener}s of this policy.</p>
+ * <p>If a request is aborted while waiting for the retry delay to elapse,
+ * it is failed when the delay elapses.</p>
+ */
+@ManagedObject("A policy to retry failed requests")
+public class RetryPolicy
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RetryPolicy.class);
+    private static final long TOKEN = 1000;
+
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
+    private final LongAdder retries = new LongAdder();
+    private final LongAdder exhausted = new LongAdder();
+    private final AtomicLong budget = new AtomicLong();
+    private int maxAttempts = 3;
+    private long initialDelay = 100;
+    private long maxDelay = 10000;
+    private double multiplier = 2.0D;
+    private double jitter = 0.5D;
+    private long maxRetryAfter = 60000;
+    private Set<Integer> retryableStatuses = Set.of(
+        HttpStatus.TOO_MANY_REQUESTS_429,
+        HttpStatus.BAD_GATEWAY_502,
+        HttpStatus.SERVICE_UNAVAILABLE_503,
+        HttpStatus.GATEWAY_TIMEOUT_504);
+    private boolean retryNonIdempotent;
+    private double budgetRatio = 0.2D;
+    private int budgetCapacity = 10;
+
+    public RetryPolicy()
+    {
+        budget.set(budgetCapacity * TOKEN);
+    }
+
+    /**
+     * @return the max number of attempts, including the first one
+     */
+    @ManagedAttribute("The max number of attempts, including the first one")
+    public int getMaxAttempts()
+    {
+        return maxAttempts;
+    }
+
+    /**
+     * @param maxAttempts the max number of attempts, including the first one
+     */
+    public void setMaxAttempts(int maxAttempts)
+    {
+        if (maxAttempts < 1)
+            throw new IllegalArgumentException("Invalid max attempts " + maxAttempts);
+        this.maxAttempts = maxAttempts;
+    }
+
+    /**
+     * @return the delay in milliseconds before the first retry
+     */
+    @ManagedAttribute("The delay in milliseconds before the first retry")
+    public long getInitialDelay()
+    {
+        return initialDelay;
+    }
+
+    /**
+     * @param initialDelay the delay in milliseconds before the first retry
+     */
+    public void setInitialDelay(long initialDelay)
+    {
+        if (initialDelay < 0)
+            throw new IllegalArgumentException("Invalid initial delay " + initialDelay);
+        this.initialDelay = initialDelay;
+    }
+
+    /**
+     * @return the max delay in milliseconds between attempts
+     */
+    @ManagedAttribute("The max delay in milliseconds between attempts")
+    public long getMaxDelay()
+    {
+        return maxDelay;
+    }
+
+    /**
+     * @param maxDelay the max delay in milliseconds between attempts
+     */
+    public void setMaxDelay(long maxDelay)
+    {
+        if (maxDelay < 0)
+            throw new IllegalArgumentException("Invalid max delay " + maxDelay);
+        this.maxDelay = maxDelay;
+    }
+
+    /**
+     * @return the factor the delay is multiplied by at every attempt
+     */
+    @ManagedAttribute("The factor the delay is multiplied by at every attempt")
+    public double getMultiplier()
+    {
+        return multiplier;
+    }
+
+    /**
+     * @param multiplier the factor the delay is multiplied by at every attempt
+     */
+    public void setMultiplier(double multiplier)
+    {
+        if (multiplier < 1.0D)
+            throw new IllegalArgumentException("Invalid multiplier " + multiplier);
+        this.multiplier = multiplier;
+    }
+
+    /**
+     * @return the max fraction of the delay, between 0 and 1, that is randomly subtracted from the delay
+     */
+    @ManagedAttribute("The max random fraction subtracted from the delay")
+    public double getJitter()
+    {
+        return jitter;
+    }
+
+    /**
+     * @param jitter the max fraction of the delay, between 0 and 1, that is randomly subtracted from the delay
+     */
+    public void setJitter(double jitter)
+    {
+        if (jitter < 0.0D || jitter > 1.0D)
+            throw new IllegalArgumentException("Invalid jitter " + jitter);
+        this.jitter = jitter;
+    }
+
+    /**
+     * @return the max {@code Retry-After} delay in milliseconds that is honored
+     */
+    @ManagedAttribute("The max Retry-After delay in milliseconds that is honored")
+    public long getMaxRetryAfter()
+    {
+        return maxRetryAfter;
+    }
+
+    /**
+     * @param maxRetryAfter the max {@code Retry-After} delay in milliseconds that is honored;
+     * responses with a longer {@code Retry-After} delay are not retried
+     */
+    public void setMaxRetryAfter(long maxRetryAfter)
+    {
+        this.maxRetryAfter = maxRetryAfter;
+    }
+
+    /**
+     * @return the response status codes that are retried
+     */
+    public Set<Integer> getRetryableStatuses()
+    {
+        return retryableStatuses;
+    }
+
+    /**
+     * @param statuses the response status codes that are retried
+     */
+    public void setRetryableStatuses(Set<Integer> statuses)
+    {
+        this.retryableStatuses = Set.copyOf(statuses);
+    }
+
+    /**
+     * @return whether requests with non-idempotent methods, such as {@code POST}, are retried
+     */
+    @ManagedAttribute("Whether requests with non-idempotent methods are retried")
+    public boolean isRetryNonIdempotent()
+    {
+        return retryNonIdempotent;
+    }
+
+    /**
+     * <p>Sets whether requests with non-idempotent methods, such as {@code POST}, are retried.</p>
+     * <p>This should be enabled only if the server is known to de-duplicate requests,
+     * for example via an idempotency key header.</p>
+     *
+     * @param retryNonIdempotent whether requests with non-idempotent methods are retried
+     */
+    public void setRetryNonIdempotent(boolean retryNonIdempotent)
+    {
+        this.retryNonIdempotent = retryNonIdempotent;
+    }
+
+    /**
+     * @return the fraction of retries every request adds to the retry budget, or a negative value for no budget
+     */
+    @ManagedAttribute("The fraction of retries every request adds to the retry budget")
+    public double getBudgetRatio()
+    {
+        return budgetRatio;
+    }
+
+    /**
+     * @param budgetRatio the fraction of retries every request adds to the retry budget,
+     * or a negative value to disable the retry budget
+     */
+    public void setBudgetRatio(double budgetRatio)
+    {
+        this.budgetRatio = budgetRatio;
+    }
+
+    /**
+     * @return the max number of retries the retry budget can hold
+     */
+    @ManagedAttribute("The max number of retries the retry budget can hold")
+    public int getBudgetCapacity()
+    {
+        return budgetCapacity;
+    }
+
+    /**
+     * @param budgetCapacity the max number of retries the retry budget can hold
+     */
+    public void setBudgetCapacity(int budgetCapacity)
+    {
+        if (budgetCapacity < 0)
+            throw new IllegalArgumentException("Invalid budget capacity " + budgetCapacity);
+        this.budgetCapacity = budgetCapacity;
+        budget.set(budgetCapacity * TOKEN);
+    }
+
+    @ManagedAttribute("The number of retries available in the retry budget")
+    public double getBudgetAvailable()
+    {
+        return (double)budget.get() / TOKEN;
+    }
+
+    @ManagedAttribute("The number of retries")
+    public long getRetries()
+    {
+        return retries.sum();
+    }
+
+    @ManagedAttribute("The number of retryable attempts that were not retried")
+    public long getExhaustedRetries()
+    {
+        return exhausted.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void reset()
+    {
+        retries.reset();
+        exhausted.reset();
+    }
+
+    /**
+     * @param listener the listener to notify of the attempts
+     */
+    public void addListener(Listener listener)
+    {
+        listeners.add(Objects.requireNonNull(listener));
+    }
+
+    /**
+     * @param listener the listener to remove
+     * @return whether the listener was removed
+     */
+    public boolean removeListener(Listener listener)
+    {
+        return listeners.remove(listener);
+    }
+
+    /**
+     * <p>Returns whether the given failure, that happened before a response
+     * was received, can be retried.</p>
+     * <p>By default, I/O failures such as connection failures are retried,
+     * with the exception of unknown hosts and TLS handshake failures.</p>
+     *
+     * @param failure the request failure
+     * @return whether the failure can be retried
+     */
+    protected boolean isRetryable(Throwable failure)
+    {
+        if (failure instanceof UnknownHostException || failure instanceof SSLHandshakeException)
+            return false;
+        return failure instanceof IOException;
+    }
+
+    /**
+     * <p>Returns whether the given request can be sent again.</p>
+     *
+     * @param request the request
+     * @return whether the request method and content allow the request to be sent again
+     */
+    protected boolean isRetryable(Request request)
+    {
+        Request.Content content = request.getBody();
+        if (content != null && !content.isReproducible())
+            return false;
+        if (isRetryNonIdempotent())
+            return true;
+        HttpMethod method = HttpMethod.fromString(request.getMethod());
+        return method != null && method.isIdempotent();
+    }
+
+    /**
+     * <p>Computes the delay before the given retry.</p>
+     *
+     * @param attempt the number of the attempt that failed, starting from 1
+     * @return the delay in milliseconds before the next attempt
+     */
+    protected long computeDelay(int attempt)
+    {
+        double delay = getInitialDelay() * Math.pow(getMultiplier(), attempt - 1);
+        delay = Math.min(delay, getMaxDelay());
+        delay -= delay * getJitter() * ThreadLocalRandom.current().nextDouble();
+        return Math.round(delay);
+    }
+
+    /**
+     * @param response the response
+     * @return the {@code Retry-After} delay in milliseconds, or -1 if the header is absent or invalid
+     */
+    static long parseRetryAfter(Response response)
+    {
+        String value = response.getHeaders().get(HttpHeader.RETRY_AFTER);
+        if (value == null)
+            return -1;
+        value = value.trim();
+        try
+        {
+            return TimeUnit.SECONDS.toMillis(Math.max(0, Long.parseLong(value)));
+        }
+        catch (NumberFormatException x)
+        {
+            long date = DateParser.parseDate(value);
+            if (date < 0)
+                return -1;
+            return Math.max(0, date - System.currentTimeMillis());
+        }
+    }
+
+    private void deposit()
+    {
+        if (budgetRatio < 0)
+            return;
+        long capacity = budgetCapacity * TOKEN;
+        long amount = Math.round(budgetRatio * TOKEN);
+        budget.updateAndGet(value -> Math.min(capacity, value + amount));
+    }
+
+    private boolean withdraw()
+    {
+        if (budgetRatio < 0)
+            return true;
+        while (true)
+        {
+            long value = budget.get();
+            if (value < TOKEN)
+                return false;
+            if (budget.compareAndSet(value, value - TOKEN))
+                return true;
+        }
+    }
+
+    void send(HttpRequest request, List<Response.ResponseListener> responseListeners, BiConsumer<HttpRequest, List<Response.ResponseListener>> sender)
+    {
+        deposit();
+        if (getMaxAttempts() <= 1 || !isRetryable(request))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Not retryable {}", request);
+            sender.accept(request, responseListeners);
+            return;
+        }
+        new Retry(request, responseListeners, sender).send(request);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[attempts=%d,delay=%d..%d,budget=%.1f/%d]",
+            getClass().getSimpleName(),
+            hashCode(),
+            getMaxAttempts(),
+            getInitialDelay(),
+            getMaxDelay(),
+            getBudgetAvailable(),
+            getBudgetCapacity());
+    }
+
+    /**
+     * <p>A listener of the attempts of requests sent with a {@link RetryPolicy}.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when an attempt is sent.</p>
+         *
+         * @param request the request of the attempt
+         * @param attempt the number of the attempt, starting from 1
+         */
+        public default void onAttempt(Request request, int attempt)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when an attempt is going to be retried.</p>
+         *
+         * @param request the request of the attempt
+         * @param attempt the number of the attempt, starting from 1
+         * @param response the response of the attempt, or null if it failed before a response was received
+         * @param failure the failure of the attempt, or null if it received a retryable response
+         * @param delay the delay in milliseconds before the next attempt
+         */
+        public default void onRetry(Request request, int attempt, Response response, Throwable failure, long delay)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when an attempt could have been retried,
+         * but was not because of the max attempts, the retry budget, the request
+         * timeout or the {@code Retry-After} delay.</p>
+         *
+         * @param request the request of the attempt
+         * @param attempt the number of the attempt, starting from 1
+         * @param response the response of the attempt, or null if it failed before a response was received
+         * @param failure the failure of the attempt, or null if it received a retryable response
+         */
+        public default void onExhausted(Request request, int attempt, Response response, Throwable failure)
+        {
+        }
+    }
+
+    /**
+     * <p>The state of the attempts of a request.</p>
+     */
+    private class Retry
+    {
+        private final HttpRequest request;
+        private final HttpFields headers;
+        private final InterceptorChain.Listeners listener;
+        private final BiConsumer<HttpRequest, List<Response.ResponseListener>> sender;
+        private final long timeoutNanoTime;
+        private int attempt;
+
+        private Retry(HttpRequest request, List<Response.ResponseListener> responseListeners, BiConsumer<HttpRequest, List<Response.ResponseListener>> sender)
+        {
+            this.request = request;
+            // Retries are sent with the headers before normalization.
+            this.headers = HttpFields.build(request.getHeaders()).asImmutable();
+            this.listener = new InterceptorChain.Listeners(responseListeners);
+            this.sender = sender;
+            long timeout = request.getTimeout();
+            this.timeoutNanoTime = timeout > 0 ? NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(timeout) : Long.MAX_VALUE;
+        }
+
+        private void send(HttpRequest attemptRequest)
+        {
+            ++attempt;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Attempt #{} of {}", attempt, attemptRequest);
+            for (Listener l : listeners)
+            {
+                try
+                {
+                    l.onAttempt(attemptRequest, attempt);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", l, x);
+                }
+            }
+            sender.accept(attemptRequest, List.of(new AttemptListener(attemptRequest, attempt)));
+        }
+
+        /**
+         * @return the delay before the next attempt, or -1 if the attempt must not be retried
+         */
+        private long retry(HttpRequest attemptRequest, int attemptNumber, Response response, Throwable failure)
+        {
+            if (request.getAbortCause() != null || attemptRequest.getAbortCause() != null)
+                return -1;
+
+            long delay = computeDelay(attemptNumber);
+            if (response != null)
+            {
+                long retryAfter = parseRetryAfter(response);
+                if (retryAfter > getMaxRetryAfter())
+                    return exhausted(attemptRequest, attemptNumber, response, failure, "Retry-After " + retryAfter + " ms");
+                delay = Math.max(delay, retryAfter);
+            }
+            if (attemptNumber >= getMaxAttempts())
+                return exhausted(attemptRequest, attemptNumber, response, failure, "max attempts");
+            if (timeoutNanoTime != Long.MAX_VALUE && NanoTime.until(timeoutNanoTime) <= TimeUnit.MILLISECONDS.toNanos(delay))
+                return exhausted(attemptRequest, attemptNumber, response, failure, "timeout");
+            if (!withdraw())
+                return exhausted(attemptRequest, attemptNumber, response, failure, "retry budget");
+            return delay;
+        }
+
+        private long exhausted(HttpRequest attemptRequest, int attemptNumber, Response response, Throwable failure, String reason)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Not retrying attempt #{} of {}: {}", attemptNumber, attemptRequest, reason);
+            exhausted.increment();
+            for (Listener l : listeners)
+            {
+                try
+                {
+                    l.onExhausted(attemptRequest, attemptNumber, response, failure);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", l, x);
+                }
+            }
+            return -1;
+        }
+
+        private void schedule(HttpRequest attemptRequest, int attemptNumber, Response response, Throwable failure, long delay)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Retrying attempt #{} of {} in {} ms", attemptNumber, attemptRequest, delay);
+            retries.increment();
+            for (Listener l : listeners)
+            {
+                try
+                {
+                    l.onRetry(attemptRequest, attemptNumber, response, failure, delay);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", l, x);
+                }
+            }
+            Runnable task = () -> resend(response, failure);
+            if (delay > 0)
+                request.getHttpClient().getScheduler().schedule(task, delay, TimeUnit.MILLISECONDS);
+            else
+                request.getHttpClient().getExecutor().execute(task);
+        }
+
+        private void resend(Response response, Throwable failure)
+        {
+            Throwable abort = request.getAbortCause();
+            if (abort != null)
+            {
+                Interceptor.fail(request, listener, abort);
+                return;
+            }
+            try
+            {
+                HttpRequest attemptRequest = request.copyForRetry(headers);
+                if (timeoutNanoTime != Long.MAX_VALUE)
+                    attemptRequest.timeout(Math.max(1, NanoTime.millisUntil(timeoutNanoTime)), TimeUnit.MILLISECONDS);
+                send(attemptRequest);
+            }
+            catch (Throwable x)
+            {
+                if (failure != null)
+                    x.addSuppressed(failure);
+                Interceptor.fail(request, listener, x);
+            }
+        }
+
+        /**
+         * <p>Forwards the events of an attempt to the response listeners,
+         * unless the attempt is retried.</p>
+         */
+        private class AttemptListener implements Response.Listener
+        {
+            private final List<HttpField> fields = new ArrayList<>();
+            private final HttpRequest attemptRequest;
+            private final int attemptNumber;
+            private boolean buffering;
+            private boolean forwarding;
+            private long delay = -1;
+
+            private AttemptListener(HttpRequest attemptRequest, int attemptNumber)
+            {
+                this.attemptRequest = attemptRequest;
+                this.attemptNumber = attemptNumber;
+            }
+
+            @Override
+            public void onBegin(Response response)
+            {
+                if (getRetryableStatuses().contains(response.getStatus()))
+                {
+                    // Wait for the headers, that may contain Retry-After.
+                    buffering = true;
+                    return;
+                }
+                forwarding = true;
+                listener.onBegin(response);
+            }
+
+            @Override
+            public boolean onHeader(Response response, HttpField field)
+            {
+                if (buffering)
+                {
+                    fields.add(field);
+                    return true;
+                }
+                return forwarding ? listener.onHeader(response, field) : true;
+            }
+
+            @Override
+            public void onHeaders(Response response)
+            {
+                if (buffering)
+                {
+                    buffering = false;
+                    delay = retry(attemptRequest, attemptNumber, response, null);
+                    if (delay >= 0)
+                        return;
+                    forwarding = true;
+                    listener.onBegin(response);
+                    for (HttpField field : fields)
+                    {
+                        listener.onHeader(response, field);
+                    }
+                    fields.clear();
+                }
+                if (forwarding)
+                    listener.onHeaders(response);
+            }
+
+            @Override
+            public void onBeforeContent(Response response, LongConsumer demand)
+            {
+                if (forwarding)
+                    listener.onBeforeContent(response, demand);
+                else
+                    demand.accept(1);
+            }
+
+            @Override
+            public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+            {
+                if (forwarding)
+                {
+                    listener.onContent(response, demand, content, callback);
+                }
+                else
+                {
+                    // Discard the content of the response that is retried.
+                    callback.succeeded();
+                    demand.accept(1);
+                }
+            }
+
+            @Override
+            public void onSuccess(Response response)
+            {
+                if (forwarding)
+                    listener.onSuccess(response);
+            }
+
+            @Override
+            public void onFailure(Response response, Throwable failure)
+            {
+                if (forwarding)
+                {
+                    listener.onFailure(response, failure);
+                    return;
+                }
+                if (delay < 0)
+                {
+                    // Failed before a response, or while waiting for the headers.
+                    if (isRetryable(failure))
+                        delay = retry(attemptRequest, attemptNumber, null, failure);
+                    if (delay < 0)
+                    {
+                        forwarding = true;
+                        listener.onFailure(response, failure);
+                    }
+                }
+            }
+
+            @Override
+            public void onComplete(Result result)
+            {
+                if (forwarding)
+                {
+                    listener.onComplete(result);
+                    return;
+                }
+                Response response = result.getFailure() == null ? result.getResponse() : null;
+                schedule(attemptRequest, attemptNumber, response, result.getFailure(), delay);
+            }
+        }
+    }
+}
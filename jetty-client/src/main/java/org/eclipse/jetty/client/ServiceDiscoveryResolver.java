@@ -0,0 +1,651 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.net.UnknownHostException;
+import java.util.ArrayList;
+import java.util.Comparator;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Queue;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.client.api.Destination;
+import org.eclipse.jetty.io.dns.DnsRecord;
+import org.eclipse.jetty.io.dns.DnsResolver;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.component.DumpableCollection;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link SocketAddressResolver} that performs client-side load balancing
+ * across all the addresses of a service discovered via DNS.</p>
+ * <p>Hosts registered via {@link #addService(String)} are resolved by the
+ * delegate resolver and then periodically re-resolved every
+ * {@link #getRefreshInterval() refresh interval}, while other hosts are
+ * resolved by the delegate resolver as usual.
+ * Hosts registered via {@link #addService(String, String)} are instead discovered
+ * via DNS {@code SRV} records, which require the delegate resolver to be a
+ * {@link DnsResolver}; the priority and weight of the {@code SRV} records are
+ * honored, and the port of the {@code SRV} records replaces the port of the request.</p>
+ * <p>When a new connection is opened, the address to connect to is chosen among
+ * the healthy addresses with a probability proportional to their weight and
+ * inversely proportional to the number of connections already open to them,
+ * so that connections are spread across all the addresses.
+ * An address that fails to connect is considered unhealthy for the
+ * {@link #getFailureBackoff() failure backoff}, doubled at every consecutive
+ * failure, and is only tried after the healthy addresses.</p>
+ * <p>Connections to addresses that disappear from DNS are drained: idle connections
+ * are closed immediately, while connections in use are closed when the exchange completes.</p>
+ * <p>This is typically useful to connect to Kubernetes headless services:</p>
+ * <pre>{@code
+ * HttpClient httpClient = new HttpClient();
+ * ServiceDiscoveryResolver resolver = new ServiceDiscoveryResolver(httpClient, new DnsResolver());
+ * resolver.addService("backend.namespace.svc.cluster.local");
+ * httpClient.setSocketAddressResolver(resolver);
+ * httpClient.start();
+ * }</pre>
+ */
+@ManagedObject("DNS service discovery and load balancing")
+public class ServiceDiscoveryResolver extends ContainerLifeCycle implements SocketAddressResolver
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ServiceDiscoveryResolver.class);
+
+    private final Map<String, String> registrations = new ConcurrentHashMap<>();
+    private final Map<Origin.Address, Service> services = new ConcurrentHashMap<>();
+    private final LongAdder refreshFailures = new LongAdder();
+    private final LongAdder connectFailures = new LongAdder();
+    private final LongAdder drainedConnections = new LongAdder();
+    private final HttpClient httpClient;
+    private final SocketAddressResolver resolver;
+    private long refreshInterval = 30000;
+    private long failureBackoff = 1000;
+
+    /**
+     * @param httpClient the HttpClient whose connections are load balanced
+     * @param resolver the delegate resolver that performs the DNS lookups
+     */
+    public ServiceDiscoveryResolver(HttpClient httpClient, SocketAddressResolver resolver)
+    {
+        this.httpClient = httpClient;
+        this.resolver = resolver;
+        addBean(resolver);
+    }
+
+    public HttpClient getHttpClient()
+    {
+        return httpClient;
+    }
+
+    public SocketAddressResolver getSocketAddressResolver()
+    {
+        return resolver;
+    }
+
+    /**
+     * @return the interval, in milliseconds, between DNS resolutions of the services
+     */
+    @ManagedAttribute("The interval in ms between DNS resolutions of the services")
+    public long getRefreshInterval()
+    {
+        return refreshInterval;
+    }
+
+    /**
+     * @param refreshInterval the interval, in milliseconds, between DNS resolutions of the services
+     */
+    public void setRefreshInterval(long refreshInterval)
+    {
+        if (refreshInterval <= 0)
+            throw new IllegalArgumentException("Invalid refresh interval " + refreshInterval);
+        this.refreshInterval = refreshInterval;
+    }
+
+    /**
+     * @return the time, in milliseconds, an address is considered unhealthy after a connect failure
+     */
+    @ManagedAttribute("The time in ms an address is unhealthy after a connect failure")
+    public long getFailureBackoff()
+    {
+        return failureBackoff;
+    }
+
+    /**
+     * <p>Sets the time an address is considered unhealthy after a connect failure.</p>
+     * <p>The time doubles at every consecutive connect failure, up to the
+     * {@link #getRefreshInterval() refresh interval}.</p>
+     *
+     * @param failureBackoff the time, in milliseconds, an address is considered unhealthy after a connect failure
+     */
+    public void setFailureBackoff(long failureBackoff)
+    {
+        this.failureBackoff = failureBackoff;
+    }
+
+    /**
+     * <p>Registers a service whose addresses are discovered by resolving the given host.</p>
+     *
+     * @param host the host of the service
+     */
+    public void addService(String host)
+    {
+        registrations.put(host, "");
+    }
+
+    /**
+     * <p>Registers a service whose addresses are discovered via the DNS {@code SRV}
+     * records with the given name, for example {@code _http._tcp.backend.namespace.svc.cluster.local}.</p>
+     *
+     * @param host the host of the service, as used in request URIs
+     * @param srvName the name of the {@code SRV} records of the service
+     */
+    public void addService(String host, String srvName)
+    {
+        if (!(resolver instanceof DnsResolver))
+            throw new IllegalStateException("SRV records require a " + DnsResolver.class.getSimpleName());
+        registrations.put(host, srvName);
+    }
+
+    /**
+     * @param host the host of the service to unregister
+     * @return whether the service was registered
+     */
+    public boolean removeService(String host)
+    {
+        boolean removed = registrations.remove(host) != null;
+        services.values().removeIf(service ->
+        {
+            if (!service.address.getHost().equals(host))
+                return false;
+            service.cancel();
+            return true;
+        });
+        return removed;
+    }
+
+    /**
+     * @return the hosts of the registered services
+     */
+    @ManagedAttribute("The hosts of the registered services")
+    public Set<String> getServices()
+    {
+        return Set.copyOf(registrations.keySet());
+    }
+
+    /**
+     * @param host the host of the service
+     * @param port the port of the service
+     * @return the addresses currently known for the given service, possibly empty
+     */
+    public List<InetSocketAddress> getAddresses(String host, int port)
+    {
+        Service service = services.get(new Origin.Address(host, port));
+        if (service == null)
+            return List.of();
+        return service.getEndpoints().stream()
+            .map(endpoint -> endpoint.address)
+            .collect(Collectors.toList());
+    }
+
+    @ManagedAttribute("The number of failed DNS resolutions of the services")
+    public long getRefreshFailures()
+    {
+        return refreshFailures.longValue();
+    }
+
+    @ManagedAttribute("The number of connect failures to the services")
+    public long getConnectFailures()
+    {
+        return connectFailures.longValue();
+    }
+
+    @ManagedAttribute("The number of connections drained because their address disappeared from DNS")
+    public long getDrainedConnections()
+    {
+        return drainedConnections.longValue();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        refreshFailures.reset();
+        connectFailures.reset();
+        drainedConnections.reset();
+    }
+
+    /**
+     * <p>Resolves again all the services without waiting for the refresh interval.</p>
+     */
+    @ManagedOperation(value = "Resolves again all the services", impact = "ACTION")
+    public void refresh()
+    {
+        services.values().forEach(Service::refresh);
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        services.values().forEach(Service::cancel);
+        services.clear();
+        super.doStop();
+    }
+
+    @Override
+    public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+    {
+        String srvName = registrations.get(host);
+        if (srvName == null)
+        {
+            resolver.resolve(host, port, promise);
+            return;
+        }
+        Origin.Address address = new Origin.Address(host, port);
+        services.computeIfAbsent(address, key -> new Service(key, srvName.isEmpty() ? null : srvName)).resolve(promise);
+    }
+
+    void onConnectSuccess(InetSocketAddress address)
+    {
+        for (Service service : services.values())
+        {
+            Endpoint endpoint = service.findEndpoint(address);
+            if (endpoint != null)
+                endpoint.failures.set(0);
+        }
+    }
+
+    void onConnectFailure(InetSocketAddress address, Throwable failure)
+    {
+        for (Service service : services.values())
+        {
+            Endpoint endpoint = service.findEndpoint(address);
+            if (endpoint != null)
+            {
+                connectFailures.increment();
+                endpoint.failedNanoTime = NanoTime.now();
+                int failures = endpoint.failures.incrementAndGet();
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Connect failure #{} to {} of {}", failures, address, service, failure);
+            }
+        }
+    }
+
+    /**
+     * <p>Orders the given addresses of a service, the first being the one to connect to,
+     * followed by the healthy addresses and finally the unhealthy addresses.</p>
+     *
+     * @param address the address of the service
+     * @param endpoints the endpoints of the service
+     * @return the ordered socket addresses
+     */
+    private List<InetSocketAddress> select(Origin.Address address, List<Endpoint> endpoints)
+    {
+        Map<SocketAddress, Integer> connections = countConnections(address);
+        long now = NanoTime.now();
+        List<Endpoint> healthy = new ArrayList<>();
+        List<Endpoint> unhealthy = new ArrayList<>();
+        Map<Endpoint, Double> scores = new HashMap<>();
+        for (Endpoint endpoint : endpoints)
+        {
+            scores.put(endpoint, endpoint.weight / (1.0 + connections.getOrDefault(endpoint.address, 0)));
+            if (endpoint.isHealthy(now))
+                healthy.add(endpoint);
+            else
+                unhealthy.add(endpoint);
+        }
+
+        List<InetSocketAddress> result = new ArrayList<>(endpoints.size());
+        if (!healthy.isEmpty())
+        {
+            // Pick randomly among the most preferred healthy endpoints, proportionally to their score.
+            int priority = healthy.stream().mapToInt(endpoint -> endpoint.priority).min().orElse(0);
+            List<Endpoint> candidates = healthy.stream()
+                .filter(endpoint -> endpoint.priority == priority)
+                .collect(Collectors.toList());
+            double total = candidates.stream().mapToDouble(scores::get).sum();
+            double random = ThreadLocalRandom.current().nextDouble(total);
+            Endpoint selected = candidates.get(candidates.size() - 1);
+            for (Endpoint candidate : candidates)
+            {
+                random -= scores.get(candidate);
+                if (random < 0)
+                {
+                    selected = candidate;
+                    break;
+                }
+            }
+            healthy.remove(selected);
+            result.add(selected.address);
+            healthy.sort(Comparator.<Endpoint>comparingInt(endpoint -> endpoint.priority)
+                .thenComparing(Comparator.<Endpoint>comparingDouble(scores::get).reversed()));
+        }
+        healthy.forEach(endpoint -> result.add(endpoint.address));
+        unhealthy.sort(Comparator.comparingInt(endpoint -> endpoint.failures.get()));
+        unhealthy.forEach(endpoint -> result.add(endpoint.address));
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Selected {} for {} with connections {}", result, address, connections);
+        return result;
+    }
+
+    private Map<SocketAddress, Integer> countConnections(Origin.Address address)
+    {
+        Map<SocketAddress, Integer> result = new HashMap<>();
+        for (Destination destination : httpClient.getDestinations())
+        {
+            HttpDestination httpDestination = (HttpDestination)destination;
+            if (!address.equals(httpDestination.getConnectAddress()))
+                continue;
+            ConnectionPool connectionPool = httpDestination.getConnectionPool();
+            if (connectionPool instanceof AbstractConnectionPool)
+            {
+                AbstractConnectionPool pool = (AbstractConnectionPool)connectionPool;
+                pool.getActiveConnections().forEach(connection -> result.merge(connection.getRemoteSocketAddress(), 1, Integer::sum));
+                pool.getIdleConnections().forEach(connection -> result.merge(connection.getRemoteSocketAddress(), 1, Integer::sum));
+            }
+        }
+        return result;
+    }
+
+    private void drain(Origin.Address address, Set<InetSocketAddress> removed)
+    {
+        int drained = 0;
+        for (Destination destination : httpClient.getDestinations())
+        {
+            HttpDestination httpDestination = (HttpDestination)destination;
+            if (!address.equals(httpDestination.getConnectAddress()))
+                continue;
+            ConnectionPool connectionPool = httpDestination.getConnectionPool();
+            if (connectionPool instanceof AbstractConnectionPool)
+            {
+                AbstractConnectionPool pool = (AbstractConnectionPool)connectionPool;
+                drained += pool.drain((Connection connection) -> removed.contains(connection.getRemoteSocketAddress()));
+            }
+        }
+        drainedConnections.add(drained);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Drained {} connections to {} of {}", drained, removed, address);
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        dumpObjects(out, indent, new DumpableCollection("services", services.values()));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[services=%s]", getClass().getSimpleName(), hashCode(), registrations.keySet());
+    }
+
+    private class Service
+    {
+        private final AutoLock lock = new AutoLock();
+        private final List<Promise<List<InetSocketAddress>>> waiters = new ArrayList<>();
+        private final Origin.Address address;
+        private final String srvName;
+        private List<Endpoint> endpoints;
+        private boolean resolving;
+        private Scheduler.Task task;
+        private boolean cancelled;
+
+        private Service(Origin.Address address, String srvName)
+        {
+            this.address = address;
+            this.srvName = srvName;
+        }
+
+        private List<Endpoint> getEndpoints()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                return endpoints == null ? List.of() : endpoints;
+            }
+        }
+
+        private Endpoint findEndpoint(InetSocketAddress socketAddress)
+        {
+            for (Endpoint endpoint : getEndpoints())
+            {
+                if (endpoint.address.equals(socketAddress))
+                    return endpoint;
+            }
+            return null;
+        }
+
+        private void resolve(Promise<List<InetSocketAddress>> promise)
+        {
+            List<Endpoint> endpoints;
+            try (AutoLock l = lock.lock())
+            {
+                endpoints = this.endpoints;
+                if (endpoints == null)
+                    waiters.add(promise);
+            }
+            if (endpoints == null)
+                refresh();
+            else
+                promise.succeeded(select(address, endpoints));
+        }
+
+        private void refresh()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                if (resolving || cancelled)
+                    return;
+                resolving = true;
+                if (task != null)
+                    task.cancel();
+                task = null;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Resolving {}", this);
+            lookup(Promise.from(this::resolved, this::failed));
+        }
+
+        private void lookup(Promise<List<Endpoint>> promise)
+        {
+            if (srvName == null)
+            {
+                resolver.resolve(address.getHost(), address.getPort(), Promise.from(socketAddresses ->
+                    promise.succeeded(socketAddresses.stream()
+                        .map(socketAddress -> new Endpoint(socketAddress, 0, 1))
+                        .collect(Collectors.toList())), promise::failed));
+                return;
+            }
+
+            ((DnsResolver)resolver).resolveSrv(srvName, Promise.from(records ->
+            {
+                if (records.isEmpty())
+                {
+                    promise.failed(new UnknownHostException(srvName));
+                    return;
+                }
+                Queue<Endpoint> result = new ConcurrentLinkedQueue<>();
+                AtomicInteger pending = new AtomicInteger(records.size());
+                for (DnsRecord record : records)
+                {
+                    // Records with zero weight still get a small share of the connections.
+                    int weight = Math.max(1, record.getWeight());
+                    resolver.resolve(record.getTarget(), record.getPort(), Promise.from(socketAddresses ->
+                    {
+                        socketAddresses.forEach(socketAddress -> result.add(new Endpoint(socketAddress, record.getPriority(), weight)));
+                        if (pending.decrementAndGet() == 0)
+                            complete(result, promise);
+                    }, x ->
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Could not resolve {} for {}", record, this, x);
+                        if (pending.decrementAndGet() == 0)
+                            complete(result, promise);
+                    }));
+                }
+            }, promise::failed));
+        }
+
+        private void complete(Queue<Endpoint> result, Promise<List<Endpoint>> promise)
+        {
+            if (result.isEmpty())
+                promise.failed(new UnknownHostException(srvName));
+            else
+                promise.succeeded(new ArrayList<>(result));
+        }
+
+        private void resolved(List<Endpoint> resolved)
+        {
+            Set<InetSocketAddress> removed = new HashSet<>();
+            List<Promise<List<InetSocketAddress>>> promises;
+            List<Endpoint> current;
+            try (AutoLock l = lock.lock())
+            {
+                resolving = false;
+                // Keep the health information of the addresses that are still present.
+                Map<InetSocketAddress, Endpoint> previous = new HashMap<>();
+                if (endpoints != null)
+                    endpoints.forEach(endpoint -> previous.put(endpoint.address, endpoint));
+                List<Endpoint> merged = new ArrayList<>(resolved.size());
+                Set<InetSocketAddress> seen = new HashSet<>();
+                for (Endpoint endpoint : resolved)
+                {
+                    if (!seen.add(endpoint.address))
+                        continue;
+                    Endpoint existing = previous.remove(endpoint.address);
+                    if (existing != null)
+                    {
+                        existing.priority = endpoint.priority;
+                        existing.weight = endpoint.weight;
+                        endpoint = existing;
+                    }
+                    merged.add(endpoint);
+                }
+                removed.addAll(previous.keySet());
+                endpoints = List.copyOf(merged);
+                current = endpoints;
+                promises = new ArrayList<>(waiters);
+                waiters.clear();
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Resolved {} removed {} for {}", current, removed, this);
+            if (!removed.isEmpty())
+                drain(address, removed);
+            promises.forEach(promise -> promise.succeeded(select(address, current)));
+            schedule();
+        }
+
+        private void failed(Throwable failure)
+        {
+            refreshFailures.increment();
+            List<Promise<List<InetSocketAddress>>> promises;
+            boolean resolved;
+            try (AutoLock l = lock.lock())
+            {
+                resolving = false;
+                resolved = endpoints != null;
+                promises = new ArrayList<>(waiters);
+                waiters.clear();
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not resolve {}", this, failure);
+            promises.forEach(promise -> promise.failed(failure));
+            // Keep the last known addresses, so that a DNS
+            // failure does not make the service unreachable.
+            if (resolved)
+                schedule();
+        }
+
+        private void schedule()
+        {
+            Scheduler scheduler = httpClient.getScheduler();
+            try (AutoLock l = lock.lock())
+            {
+                if (cancelled || !isRunning())
+                    return;
+                task = scheduler.schedule(this::refresh, getRefreshInterval(), TimeUnit.MILLISECONDS);
+            }
+        }
+
+        private void cancel()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                cancelled = true;
+                if (task != null)
+                    task.cancel();
+                task = null;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,srv=%s,endpoints=%s]", getClass().getSimpleName(), hashCode(), address, srvName, getEndpoints());
+        }
+    }
+
+    private class Endpoint
+    {
+        private final AtomicInteger failures = new AtomicInteger();
+        private final InetSocketAddress address;
+        private volatile int priority;
+        private volatile int weight;
+        private volatile long failedNanoTime;
+
+        private Endpoint(InetSocketAddress address, int priority, int weight)
+        {
+            this.address = address;
+            this.priority = priority;
+            this.weight = weight;
+        }
+
+        private boolean isHealthy(long now)
+        {
+            int failures = this.failures.get();
+            if (failures == 0)
+                return true;
+            long backoff = Math.min(getFailureBackoff() << Math.min(failures - 1, 20), getRefreshInterval());
+            return NanoTime.millisElapsed(failedNanoTime, now) >= backoff;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s[p=%d,w=%d,f=%d]", address, priority, weight, failures.get());
+        }
+    }
+}
@@ -0,0 +1,306 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.Closeable;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.channels.AsynchronousCloseException;
+import java.nio.channels.ClosedChannelException;
+import java.util.Objects;
+import java.util.concurrent.CompletableFuture;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.util.CapsuleResponseListener;
+import org.eclipse.jetty.http.CapsuleConnection;
+import org.eclipse.jetty.http.CapsuleGenerator;
+import org.eclipse.jetty.http.CapsuleParser;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.HostPort;
+import org.eclipse.jetty.util.URIUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A UDP tunnel established through a proxy that supports
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9298">RFC 9298</a>,
+ * also known as a MASQUE proxy.</p>
+ * <p>The tunnel is opened with an extended CONNECT request with the {@code connect-udp}
+ * protocol, so {@link HttpClient} must be configured with an HTTP/2 or HTTP/3 transport.
+ * UDP payloads are exchanged with the target as {@code DATAGRAM} capsules in the content
+ * of the tunnel.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * UDPTunnel tunnel = new UDPTunnel(httpClient, URI.create("https://proxy.example.com"), "dns.example.com", 53);
+ * tunnel.open(payload -> process(payload)).get(5, TimeUnit.SECONDS);
+ * tunnel.send(ByteBuffer.wrap(query));
+ * ...
+ * tunnel.close();
+ * }</pre>
+ * <p>Instances of this class are not reusable, so one must be allocated for each tunnel.</p>
+ */
+public class UDPTunnel implements Closeable
+{
+    /**
+     * The extended CONNECT protocol used to proxy UDP.
+     */
+    public static final String PROTOCOL = "connect-udp";
+    private static final String CAPSULE_PROTOCOL = "Capsule-Protocol";
+    private static final Logger LOG = LoggerFactory.getLogger(UDPTunnel.class);
+
+    private final CompletableFuture<UDPTunnel> opened = new CompletableFuture<>();
+    private final CompletableFuture<Void> closed = new CompletableFuture<>();
+    private final HttpClient client;
+    private final URI proxyURI;
+    private final String host;
+    private final int port;
+    private Consumer<Request> requestCustomizer;
+    private int maxDatagramSize = 65527;
+    private volatile Request request;
+    private volatile CapsuleConnection connection;
+
+    /**
+     * @param client the HttpClient used to send the {@code connect-udp} request
+     * @param proxyURI the URI of the proxy, whose path is ignored
+     * @param host the UDP target host
+     * @param port the UDP target port
+     */
+    public UDPTunnel(HttpClient client, URI proxyURI, String host, int port)
+    {
+        this.client = Objects.requireNonNull(client);
+        this.proxyURI = Objects.requireNonNull(proxyURI);
+        this.host = Objects.requireNonNull(host);
+        this.port = port;
+    }
+
+    /**
+     * <p>Returns the path of the {@code connect-udp} request for the given target, as specified
+     * by the default URI template {@code /.well-known/masque/udp/{target_host}/{target_port}/}.</p>
+     *
+     * @param host the UDP target host
+     * @param port the UDP target port
+     * @return the path of the {@code connect-udp} request
+     */
+    public static String toPath(String host, int port)
+    {
+        // IPv6 addresses are not bracketed, and their colons are percent-encoded.
+        String address = HostPort.normalizeHost(host);
+        if (address.startsWith("["))
+            address = address.substring(1, address.length() - 1);
+        return "/.well-known/masque/udp/" + URIUtil.encodePath(address).replace(":", "%3A") + "/" + port + "/";
+    }
+
+    public URI getProxyURI()
+    {
+        return proxyURI;
+    }
+
+    public String getHost()
+    {
+        return host;
+    }
+
+    public int getPort()
+    {
+        return port;
+    }
+
+    /**
+     * @param requestCustomizer a function that customizes the {@code connect-udp} request before it is sent,
+     * for example to add proxy authentication headers
+     */
+    public void setRequestCustomizer(Consumer<Request> requestCustomizer)
+    {
+        this.requestCustomizer = requestCustomizer;
+    }
+
+    /**
+     * @return the max UDP payload size, in bytes, received from the proxy
+     */
+    public int getMaxDatagramSize()
+    {
+        return maxDatagramSize;
+    }
+
+    /**
+     * @param maxDatagramSize the max UDP payload size, in bytes, received from the proxy
+     */
+    public void setMaxDatagramSize(int maxDatagramSize)
+    {
+        this.maxDatagramSize = maxDatagramSize;
+    }
+
+    /**
+     * <p>Opens this tunnel by sending the {@code connect-udp} request to the proxy.</p>
+     * <p>The listener is invoked with each UDP payload received from the target, by
+     * the thread that reads from the tunnel, so it must not block; the listener may
+     * retain the UDP payload buffer.</p>
+     *
+     * @param listener the listener of the UDP payloads received from the target
+     * @return a future completed when the tunnel is established, or completed
+     * exceptionally with a {@link HttpResponseException} if the proxy rejected the tunnel
+     */
+    public CompletableFuture<UDPTunnel> open(Consumer<ByteBuffer> listener)
+    {
+        Objects.requireNonNull(listener);
+        HttpRequest request = (HttpRequest)client.newRequest(proxyURI)
+            .method(HttpMethod.CONNECT)
+            .path(toPath(host, port))
+            .headers(headers -> headers.put(CAPSULE_PROTOCOL, "?1"));
+        request.upgradeProtocol(PROTOCOL);
+        if (requestCustomizer != null)
+            requestCustomizer.accept(request);
+        this.request = request;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Opening {}", this);
+        CapsuleResponseListener responseListener = new CapsuleResponseListener(client, new DatagramListener(listener), getMaxDatagramSize() + 16);
+        responseListener.getConnection().whenComplete((connection, failure) ->
+        {
+            if (failure == null)
+            {
+                this.connection = connection;
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Opened {} over {}", this, connection.getEndPoint());
+                opened.complete(this);
+            }
+            else
+            {
+                onClosed(failure);
+            }
+        });
+        request.send(responseListener);
+        return opened;
+    }
+
+    /**
+     * @return whether this tunnel is established and not closed yet
+     */
+    public boolean isOpen()
+    {
+        return connection != null && !closed.isDone();
+    }
+
+    /**
+     * <p>Sends the given UDP payload to the target.</p>
+     * <p>The UDP payload is copied, so the buffer may be reused as soon as this method returns.</p>
+     *
+     * @param payload the UDP payload
+     * @return a future completed when the UDP payload has been written to the tunnel
+     */
+    public CompletableFuture<Void> send(ByteBuffer payload)
+    {
+        CapsuleConnection connection = this.connection;
+        if (connection == null)
+            return CompletableFuture.failedFuture(new IllegalStateException("UDP tunnel not open: " + this));
+        CompletableFuture<Void> result = new CompletableFuture<>();
+        connection.send(CapsuleGenerator.generateDatagram(0, payload), Callback.from(() -> result.complete(null), result::completeExceptionally));
+        return result;
+    }
+
+    /**
+     * @return a future completed when this tunnel is closed, or completed exceptionally
+     * if this tunnel is closed because of a failure
+     */
+    public CompletableFuture<Void> whenClosed()
+    {
+        return closed;
+    }
+
+    /**
+     * <p>Closes this tunnel.</p>
+     * <p>If the tunnel is established, its output is shut down so that the proxy
+     * closes the UDP socket and ends the tunnel; otherwise the {@code connect-udp}
+     * request is aborted.</p>
+     */
+    @Override
+    public void close()
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Closing {}", this);
+        CapsuleConnection connection = this.connection;
+        if (connection != null)
+        {
+            connection.getEndPoint().shutdownOutput();
+        }
+        else
+        {
+            Request request = this.request;
+            if (request != null)
+                request.abort(new AsynchronousCloseException());
+        }
+    }
+
+    private void onClosed(Throwable failure)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Closed {}", this, failure);
+        if (failure == null)
+        {
+            opened.completeExceptionally(new ClosedChannelException());
+            closed.complete(null);
+        }
+        else
+        {
+            opened.completeExceptionally(failure);
+            closed.completeExceptionally(failure);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s:%d via %s]", getClass().getSimpleName(), hashCode(), host, port, proxyURI);
+    }
+
+    private class DatagramListener implements CapsuleConnection.Listener
+    {
+        private final Consumer<ByteBuffer> listener;
+
+        private DatagramListener(Consumer<ByteBuffer> listener)
+        {
+            this.listener = listener;
+        }
+
+        @Override
+        public void onCapsule(long type, ByteBuffer value)
+        {
+            // Unknown capsule types are ignored, as specified by RFC 9297.
+            if (type != CapsuleParser.DATAGRAM)
+                return;
+            long contextId = CapsuleParser.decodeVarInt(value);
+            if (contextId != 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("dropped datagram with unknown context ID {} on {}", contextId, UDPTunnel.this);
+                return;
+            }
+            try
+            {
+                listener.accept(value);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+
+        @Override
+        public void onClose(Throwable failure)
+        {
+            onClosed(failure);
+        }
+    }
+}
@@ -27,8 +27,11 @@ import java.util.concurrent.ExecutionException;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
 import java.util.function.Consumer;
+import java.util.function.Supplier;
 
 import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.ProxyConfiguration;
+import org.eclipse.jetty.client.RetryPolicy;
 import org.eclipse.jetty.client.util.InputStreamResponseListener;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
@@ -168,6 +171,22 @@ public interface Request
      */
     HttpFields getHeaders();
 
+    /**
+     * <p>Sets a supplier of the trailers to send after the request content.</p>
+     * <p>The supplier is invoked once, after the last request content has been sent;
+     * over HTTP/1.1, a request with trailers is always sent with chunked content,
+     * while HTTP/2 and HTTP/3 send the trailers in a final HEADERS frame.</p>
+     *
+     * @param trailers a supplier of the request trailers
+     * @return this request object
+     */
+    Request trailers(Supplier<HttpFields> trailers);
+
+    /**
+     * @return the supplier of the request trailers, or {@code null} if no trailers are sent
+     */
+    Supplier<HttpFields> getTrailers();
+
     /**
      * Modifies the headers of this request.
      *
@@ -232,6 +251,84 @@ public interface Request
      */
     Object getTag();
 
+    /**
+     * <p>Specifies the Unix-Domain path to connect to, so that a single
+     * {@link org.eclipse.jetty.client.HttpClient} can send requests to both
+     * TCP and Unix-Domain servers.</p>
+     * <p>The URI scheme, host and port are still used to generate the request
+     * line and the {@code Host} header, and to identify the destination
+     * along with the Unix-Domain path.</p>
+     *
+     * @param path the Unix-Domain path to connect to, or null to connect via TCP
+     * @return this request object
+     */
+    default Request unixDomainPath(Path path)
+    {
+        return this;
+    }
+
+    /**
+     * @return the Unix-Domain path to connect to, or null to connect via TCP
+     */
+    default Path getUnixDomainPath()
+    {
+        return null;
+    }
+
+    /**
+     * <p>Specifies the proxies to use for this request, overriding the
+     * {@link HttpClient#getProxyConfiguration() HttpClient proxy configuration}.</p>
+     * <p>The proxies are tried in order: if a connection to a proxy cannot be
+     * established, the request fails over to the next proxy.</p>
+     *
+     * @param proxies the ordered list of proxies to use, or null or empty to use the proxy configuration
+     * @return this request object
+     * @see ProxyConfiguration#select(org.eclipse.jetty.client.Origin, List)
+     */
+    default Request proxies(List<ProxyConfiguration.Proxy> proxies)
+    {
+        return this;
+    }
+
+    /**
+     * @return the proxies to use for this request, or null to use the proxy configuration
+     */
+    default List<ProxyConfiguration.Proxy> getProxies()
+    {
+        return null;
+    }
+
+    /**
+     * <p>Specifies the policy to retry this request if it fails.</p>
+     * <p>By default, requests use the {@link HttpClient#getRetryPolicy() HttpClient retry policy}.</p>
+     *
+     * @param retryPolicy the retry policy, or null to never retry this request
+     * @return this request object
+     */
+    default Request retryPolicy(RetryPolicy retryPolicy)
+    {
+        return this;
+    }
+
+    /**
+     * @return the policy to retry this request if it fails, or null if this request is never retried
+     */
+    default RetryPolicy getRetryPolicy()
+    {
+        return null;
+    }
+
+    /**
+     * <p>Returns the connection the request is sent over, for example to find out
+     * the remote address the connection is established to.</p>
+     *
+     * @return the connection the request is sent over, or null if the request has not been sent yet
+     */
+    default Connection getConnection()
+    {
+        return null;
+    }
+
     /**
      * @param name the name of the attribute
      * @param value the value of the attribute
@@ -16,6 +16,7 @@ package org.eclipse.jetty.client.api;
 import java.nio.ByteBuffer;
 import java.util.EventListener;
 import java.util.List;
+import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.Flow;
 import java.util.function.LongConsumer;
 
@@ -69,6 +70,21 @@ public interface Response
      */
     HttpFields getHeaders();
 
+    /**
+     * @return the trailers of this response, or {@code null} if no trailers have been received
+     * @see #getTrailersFuture()
+     */
+    HttpFields getTrailers();
+
+    /**
+     * <p>Returns a future completed when this response content has been fully received.</p>
+     * <p>The future is completed with the response trailers, or with {@link HttpFields#EMPTY}
+     * if the response has no trailers, or completed exceptionally if the response fails.</p>
+     *
+     * @return a future completed with the response trailers
+     */
+    CompletableFuture<HttpFields> getTrailersFuture();
+
     /**
      * Attempts to abort the receive of this response.
      *
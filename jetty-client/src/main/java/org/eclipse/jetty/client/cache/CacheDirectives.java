@@ -0,0 +1,109 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.util.HashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpHeaderValue;
+import org.eclipse.jetty.util.QuotedStringTokenizer;
+
+/**
+ * <p>The {@code Cache-Control} directives of a request or response,
+ * as defined by <a href="https://www.rfc-editor.org/rfc/rfc9111#section-5.2">RFC 9111</a>.</p>
+ */
+class CacheDirectives
+{
+    static final String MAX_AGE = "max-age";
+    static final String MAX_STALE = "max-stale";
+    static final String MIN_FRESH = "min-fresh";
+    static final String MUST_REVALIDATE = "must-revalidate";
+    static final String NO_CACHE = "no-cache";
+    static final String NO_STORE = "no-store";
+    static final String ONLY_IF_CACHED = "only-if-cached";
+    static final String PUBLIC = "public";
+
+    private final Map<String, String> directives = new HashMap<>();
+
+    /**
+     * @param fields the request headers
+     * @return the directives of the request, where {@code Pragma: no-cache} is
+     * treated as {@code no-cache} if there is no {@code Cache-Control} header
+     */
+    static CacheDirectives ofRequest(HttpFields fields)
+    {
+        CacheDirectives result = new CacheDirectives(fields);
+        if (!fields.contains(HttpHeader.CACHE_CONTROL) && fields.contains(HttpHeader.PRAGMA, HttpHeaderValue.NO_CACHE.asString()))
+            result.directives.put(NO_CACHE, null);
+        return result;
+    }
+
+    /**
+     * @param fields the response headers
+     * @return the directives of the response
+     */
+    static CacheDirectives ofResponse(HttpFields fields)
+    {
+        return new CacheDirectives(fields);
+    }
+
+    private CacheDirectives(HttpFields fields)
+    {
+        List<String> values = fields.getCSV(HttpHeader.CACHE_CONTROL, false);
+        for (String value : values)
+        {
+            int equals = value.indexOf('=');
+            String name = (equals < 0 ? value : value.substring(0, equals)).trim().toLowerCase(Locale.ENGLISH);
+            String argument = equals < 0 ? null : QuotedStringTokenizer.unquote(value.substring(equals + 1).trim());
+            if (!name.isEmpty())
+                directives.putIfAbsent(name, argument);
+        }
+    }
+
+    boolean has(String name)
+    {
+        return directives.containsKey(name);
+    }
+
+    /**
+     * @param name the directive name
+     * @return the directive argument in seconds, or -1 if the directive is
+     * absent, has no argument or the argument is not a valid number of seconds
+     */
+    long getSeconds(String name)
+    {
+        String value = directives.get(name);
+        if (value == null)
+            return -1;
+        try
+        {
+            long seconds = Long.parseLong(value);
+            return seconds < 0 ? -1 : seconds;
+        }
+        catch (NumberFormatException x)
+        {
+            return -1;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), directives);
+    }
+}
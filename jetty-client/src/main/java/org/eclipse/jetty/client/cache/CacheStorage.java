@@ -0,0 +1,51 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+/**
+ * <p>The storage of the responses cached by {@link CachingInterceptor}.</p>
+ * <p>Implementations must be thread-safe, and may evict entries at any time,
+ * for example to honor a maximum size.</p>
+ *
+ * @see HeapCacheStorage
+ * @see FileCacheStorage
+ */
+public interface CacheStorage
+{
+    /**
+     * @param key the cache key
+     * @return the response stored with the given key, or null if there is no such response
+     */
+    public CachedResponse get(String key);
+
+    /**
+     * <p>Stores the given response with the given key, replacing any existing response.</p>
+     *
+     * @param key the cache key
+     * @param response the response to store
+     */
+    public void put(String key, CachedResponse response);
+
+    /**
+     * <p>Removes the response stored with the given key, if any.</p>
+     *
+     * @param key the cache key
+     */
+    public void remove(String key);
+
+    /**
+     * <p>Removes all the stored responses.</p>
+     */
+    public void clear();
+}
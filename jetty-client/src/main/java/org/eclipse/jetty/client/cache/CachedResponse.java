@@ -0,0 +1,228 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.util.Collections;
+import java.util.EnumSet;
+import java.util.LinkedHashMap;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.http.DateParser;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+
+/**
+ * <p>A response stored by a {@link CacheStorage}.</p>
+ * <p>A cached response holds the response status, headers and content, the
+ * times the request was sent and the response received, used to compute the
+ * response age, and the values of the request headers nominated by the
+ * {@code Vary} response header, used to match subsequent requests.</p>
+ * <p>Instances are immutable.</p>
+ */
+public class CachedResponse
+{
+    private static final EnumSet<HttpHeader> NOT_UPDATED_HEADERS = EnumSet.of(
+        HttpHeader.CONTENT_LENGTH,
+        HttpHeader.CONTENT_ENCODING,
+        HttpHeader.TRANSFER_ENCODING,
+        HttpHeader.CONTENT_RANGE
+    );
+
+    private final int status;
+    private final HttpFields headers;
+    private final byte[] content;
+    private final Map<String, String> varyHeaders;
+    private final long requestTime;
+    private final long responseTime;
+
+    /**
+     * @param status the response status code
+     * @param headers the response headers
+     * @param content the response content
+     * @param varyHeaders the lower-case names and the values of the request headers
+     * nominated by the {@code Vary} response header
+     * @param requestTime the time, in milliseconds since the epoch, the request was sent
+     * @param responseTime the time, in milliseconds since the epoch, the response was received
+     */
+    public CachedResponse(int status, HttpFields headers, byte[] content, Map<String, String> varyHeaders, long requestTime, long responseTime)
+    {
+        this.status = status;
+        this.headers = headers.asImmutable();
+        this.content = Objects.requireNonNull(content);
+        this.varyHeaders = Collections.unmodifiableMap(new LinkedHashMap<>(varyHeaders));
+        this.requestTime = requestTime;
+        this.responseTime = responseTime;
+    }
+
+    public int getStatus()
+    {
+        return status;
+    }
+
+    public HttpFields getHeaders()
+    {
+        return headers;
+    }
+
+    /**
+     * @return the response content, which must not be modified
+     */
+    public byte[] getContent()
+    {
+        return content;
+    }
+
+    public Map<String, String> getVaryHeaders()
+    {
+        return varyHeaders;
+    }
+
+    public long getRequestTime()
+    {
+        return requestTime;
+    }
+
+    public long getResponseTime()
+    {
+        return responseTime;
+    }
+
+    /**
+     * @param requestHeaders the headers of a request
+     * @return whether the given request headers match the values of the
+     * request headers nominated by the {@code Vary} response header
+     */
+    public boolean matches(HttpFields requestHeaders)
+    {
+        for (Map.Entry<String, String> entry : varyHeaders.entrySet())
+        {
+            if (!entry.getValue().equals(varyValue(requestHeaders, entry.getKey())))
+                return false;
+        }
+        return true;
+    }
+
+    /**
+     * <p>Returns the freshness lifetime of this response, computed from the
+     * {@code max-age} directive, or from the {@code Expires} and
+     * {@code Date} headers, or heuristically from the {@code Last-Modified} header.</p>
+     *
+     * @param heuristicMaxAge the max heuristic freshness lifetime, in milliseconds
+     * @return the freshness lifetime in milliseconds
+     */
+    long getFreshnessLifetime(long heuristicMaxAge)
+    {
+        CacheDirectives directives = CacheDirectives.ofResponse(headers);
+        if (directives.has(CacheDirectives.MAX_AGE))
+            return TimeUnit.SECONDS.toMillis(Math.max(0, directives.getSeconds(CacheDirectives.MAX_AGE)));
+
+        long date = date(HttpHeader.DATE);
+        if (date < 0)
+            date = responseTime;
+
+        String expires = headers.get(HttpHeader.EXPIRES);
+        if (expires != null)
+        {
+            // An invalid Expires value represents a time in the past.
+            long expiresTime = DateParser.parseDate(expires);
+            return expiresTime < 0 ? 0 : Math.max(0, expiresTime - date);
+        }
+
+        long lastModified = date(HttpHeader.LAST_MODIFIED);
+        if (lastModified >= 0 && lastModified < date)
+            return Math.min(heuristicMaxAge, (date - lastModified) / 10);
+
+        return 0;
+    }
+
+    /**
+     * <p>Returns the current age of this response, as defined by
+     * <a href="https://www.rfc-editor.org/rfc/rfc9111#section-4.2.3">RFC 9111</a>.</p>
+     *
+     * @param now the current time in milliseconds since the epoch
+     * @return the current age in milliseconds
+     */
+    long getAge(long now)
+    {
+        long date = date(HttpHeader.DATE);
+        long apparentAge = date < 0 ? 0 : Math.max(0, responseTime - date);
+        long ageValue = 0;
+        String age = headers.get(HttpHeader.AGE);
+        if (age != null)
+        {
+            try
+            {
+                ageValue = TimeUnit.SECONDS.toMillis(Math.max(0, Long.parseLong(age.trim())));
+            }
+            catch (NumberFormatException ignored)
+            {
+                // Ignore invalid Age values.
+            }
+        }
+        long responseDelay = Math.max(0, responseTime - requestTime);
+        long correctedInitialAge = Math.max(apparentAge, ageValue + responseDelay);
+        long residentTime = Math.max(0, now - responseTime);
+        return correctedInitialAge + residentTime;
+    }
+
+    /**
+     * <p>Returns a copy of this response updated with the headers of a
+     * {@code 304 Not Modified} response that validated this response.</p>
+     *
+     * @param notModifiedHeaders the headers of the 304 response
+     * @param requestTime the time the revalidation request was sent
+     * @param responseTime the time the 304 response was received
+     * @return a new response with the updated headers and times
+     */
+    CachedResponse revalidate(HttpFields notModifiedHeaders, long requestTime, long responseTime)
+    {
+        HttpFields.Mutable updated = HttpFields.build(headers);
+        for (HttpField field : notModifiedHeaders)
+        {
+            HttpHeader header = field.getHeader();
+            if (header != null && NOT_UPDATED_HEADERS.contains(header))
+                continue;
+            updated.remove(field.getName());
+        }
+        for (HttpField field : notModifiedHeaders)
+        {
+            HttpHeader header = field.getHeader();
+            if (header != null && NOT_UPDATED_HEADERS.contains(header))
+                continue;
+            updated.add(field);
+        }
+        return new CachedResponse(status, updated, content, varyHeaders, requestTime, responseTime);
+    }
+
+    private long date(HttpHeader header)
+    {
+        String value = headers.get(header);
+        return value == null ? -1 : DateParser.parseDate(value);
+    }
+
+    static String varyValue(HttpFields requestHeaders, String name)
+    {
+        return String.join(",", requestHeaders.getValuesList(name)).replace(" ", "").toLowerCase(Locale.ENGLISH);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%d,%d bytes,vary=%s]", getClass().getSimpleName(), hashCode(), status, content.length, varyHeaders);
+    }
+}
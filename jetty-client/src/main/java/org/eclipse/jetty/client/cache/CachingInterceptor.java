@@ -0,0 +1,682 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.io.ByteArrayOutputStream;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.util.EnumSet;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.LongConsumer;
+import java.util.function.Predicate;
+
+import org.eclipse.jetty.client.ContentDecoder;
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.Interceptor;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link Interceptor} that implements a private HTTP cache, as defined by
+ * <a href="https://www.rfc-editor.org/rfc/rfc9111">RFC 9111</a>.</p>
+ * <p>Cacheable responses to {@code GET} requests are stored in a {@link CacheStorage},
+ * and subsequent {@code GET} or {@code HEAD} requests for the same URI are served
+ * from the storage without sending the request, as long as the stored response is
+ * fresh and its {@code Vary} request headers match.</p>
+ * <p>Stale stored responses that have a validator ({@code ETag} or {@code Last-Modified})
+ * are revalidated by sending the request with the {@code If-None-Match} and
+ * {@code If-Modified-Since} headers; a {@code 304 Not Modified} response refreshes
+ * the stored response, which is then used to respond; other responses replace the
+ * stored response.</p>
+ * <p>The {@code Cache-Control} request directives {@code no-cache}, {@code no-store},
+ * {@code max-age}, {@code max-stale}, {@code min-fresh} and {@code only-if-cached}
+ * are supported, along with the response directives {@code no-cache}, {@code no-store},
+ * {@code max-age} and {@code must-revalidate}.
+ * Requests using unsafe methods such as {@code POST} invalidate the stored responses for
+ * the request URI and the {@code Location} and {@code Content-Location} response URIs.</p>
+ * <p>Requests that already have conditional headers, for example because the application
+ * performs its own revalidation, are not served from the cache.</p>
+ * <p>The cache may be enabled for all requests sent by a {@link HttpClient}, or only for
+ * some destinations using a {@link #setPredicate(Predicate) predicate}:</p>
+ * <pre>{@code
+ * CachingInterceptor cache = new CachingInterceptor(httpClient, new HeapCacheStorage());
+ * cache.setPredicate(request -> "api.example.com".equals(request.getHost()));
+ * httpClient.addInterceptor(cache);
+ * }</pre>
+ */
+@ManagedObject("An HTTP cache of responses")
+public class CachingInterceptor implements Interceptor
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CachingInterceptor.class);
+    private static final Set<Integer> HEURISTICALLY_CACHEABLE = Set.of(
+        HttpStatus.OK_200,
+        HttpStatus.NON_AUTHORITATIVE_INFORMATION_203,
+        HttpStatus.NO_CONTENT_204,
+        HttpStatus.MULTIPLE_CHOICES_300,
+        HttpStatus.MOVED_PERMANENTLY_301,
+        HttpStatus.PERMANENT_REDIRECT_308,
+        HttpStatus.NOT_FOUND_404,
+        HttpStatus.METHOD_NOT_ALLOWED_405,
+        HttpStatus.GONE_410,
+        HttpStatus.URI_TOO_LONG_414,
+        HttpStatus.NOT_IMPLEMENTED_501
+    );
+    private static final Set<Integer> EXPLICITLY_CACHEABLE = Set.of(
+        HttpStatus.OK_200,
+        HttpStatus.NON_AUTHORITATIVE_INFORMATION_203,
+        HttpStatus.NO_CONTENT_204,
+        HttpStatus.MULTIPLE_CHOICES_300,
+        HttpStatus.MOVED_PERMANENTLY_301,
+        HttpStatus.FOUND_302,
+        HttpStatus.SEE_OTHER_303,
+        HttpStatus.TEMPORARY_REDIRECT_307,
+        HttpStatus.PERMANENT_REDIRECT_308,
+        HttpStatus.NOT_FOUND_404,
+        HttpStatus.METHOD_NOT_ALLOWED_405,
+        HttpStatus.GONE_410,
+        HttpStatus.URI_TOO_LONG_414,
+        HttpStatus.NOT_IMPLEMENTED_501
+    );
+    private static final EnumSet<HttpHeader> CONDITIONAL_HEADERS = EnumSet.of(
+        HttpHeader.IF_MATCH,
+        HttpHeader.IF_NONE_MATCH,
+        HttpHeader.IF_MODIFIED_SINCE,
+        HttpHeader.IF_UNMODIFIED_SINCE,
+        HttpHeader.IF_RANGE,
+        HttpHeader.RANGE
+    );
+
+    private final LongAdder hits = new LongAdder();
+    private final LongAdder misses = new LongAdder();
+    private final LongAdder revalidations = new LongAdder();
+    private final LongAdder validations = new LongAdder();
+    private final LongAdder stores = new LongAdder();
+    private final LongAdder invalidations = new LongAdder();
+    private final HttpClient httpClient;
+    private final CacheStorage storage;
+    private Predicate<Request> predicate = request -> true;
+    private int maxContentLength = 2 * 1024 * 1024;
+    private long heuristicMaxAge = TimeUnit.DAYS.toMillis(1);
+
+    /**
+     * @param httpClient the HttpClient this interceptor is added to
+     * @param storage the storage of the cached responses
+     */
+    public CachingInterceptor(HttpClient httpClient, CacheStorage storage)
+    {
+        this.httpClient = Objects.requireNonNull(httpClient);
+        this.storage = Objects.requireNonNull(storage);
+    }
+
+    public CacheStorage getCacheStorage()
+    {
+        return storage;
+    }
+
+    public Predicate<Request> getPredicate()
+    {
+        return predicate;
+    }
+
+    /**
+     * @param predicate the predicate that selects the requests that are subject
+     * to caching, for example only the requests to certain destinations
+     */
+    public void setPredicate(Predicate<Request> predicate)
+    {
+        this.predicate = Objects.requireNonNull(predicate);
+    }
+
+    @ManagedAttribute("The max length of the content of the responses to cache")
+    public int getMaxContentLength()
+    {
+        return maxContentLength;
+    }
+
+    /**
+     * @param maxContentLength the max length of the content of the responses to cache;
+     * responses with larger content are not cached
+     */
+    public void setMaxContentLength(int maxContentLength)
+    {
+        this.maxContentLength = maxContentLength;
+    }
+
+    @ManagedAttribute("The max heuristic freshness lifetime in milliseconds")
+    public long getHeuristicMaxAge()
+    {
+        return heuristicMaxAge;
+    }
+
+    /**
+     * @param heuristicMaxAge the max freshness lifetime, in milliseconds, computed
+     * heuristically from the {@code Last-Modified} header for responses that do not
+     * have explicit freshness information
+     */
+    public void setHeuristicMaxAge(long heuristicMaxAge)
+    {
+        this.heuristicMaxAge = heuristicMaxAge;
+    }
+
+    @ManagedAttribute("The number of requests served from the cache without being sent")
+    public long getHits()
+    {
+        return hits.longValue();
+    }
+
+    @ManagedAttribute("The number of requests sent because they could not be served from the cache")
+    public long getMisses()
+    {
+        return misses.longValue();
+    }
+
+    @ManagedAttribute("The number of requests sent to revalidate a stored response")
+    public long getRevalidations()
+    {
+        return revalidations.longValue();
+    }
+
+    @ManagedAttribute("The number of revalidations that resulted in a 304 response")
+    public long getValidations()
+    {
+        return validations.longValue();
+    }
+
+    @ManagedAttribute("The number of responses stored")
+    public long getStores()
+    {
+        return stores.longValue();
+    }
+
+    @ManagedAttribute("The number of stored responses invalidated by unsafe requests")
+    public long getInvalidations()
+    {
+        return invalidations.longValue();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        hits.reset();
+        misses.reset();
+        revalidations.reset();
+        validations.reset();
+        stores.reset();
+        invalidations.reset();
+    }
+
+    @ManagedOperation(value = "Removes all the stored responses", impact = "ACTION")
+    public void clear()
+    {
+        storage.clear();
+    }
+
+    @Override
+    public void intercept(Request request, Response.Listener listener, Chain chain)
+    {
+        URI uri = request.getURI();
+        if (uri == null || !predicate.test(request))
+        {
+            chain.proceed(request, listener);
+            return;
+        }
+
+        String method = request.getMethod();
+        boolean get = HttpMethod.GET.is(method);
+        if (!get && !HttpMethod.HEAD.is(method))
+        {
+            HttpMethod httpMethod = HttpMethod.fromString(method);
+            if (httpMethod == null || !httpMethod.isSafe())
+                chain.proceed(request, new InvalidatingListener(listener, uri));
+            else
+                chain.proceed(request, listener);
+            return;
+        }
+
+        HttpFields requestHeaders = HttpFields.build(request.getHeaders()).asImmutable();
+        if (requestHeaders.contains(CONDITIONAL_HEADERS))
+        {
+            chain.proceed(request, listener);
+            return;
+        }
+
+        long requestTime = System.currentTimeMillis();
+        String key = keyOf(uri);
+        CacheDirectives requestDirectives = CacheDirectives.ofRequest(requestHeaders);
+        CachedResponse cached = storage.get(key);
+        if (cached != null && !cached.matches(requestHeaders))
+            cached = null;
+
+        if (cached != null && isServable(cached, requestDirectives, requestTime))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Serving {} from cache {}", request, cached);
+            hits.increment();
+            respond(request, listener, cached, requestTime, get);
+            return;
+        }
+
+        if (requestDirectives.has(CacheDirectives.ONLY_IF_CACHED))
+        {
+            misses.increment();
+            Interceptor.respond(request, listener, HttpStatus.GATEWAY_TIMEOUT_504, null, null);
+            return;
+        }
+
+        if (get && cached != null && hasValidators(cached))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Revalidating {} for {}", cached, request);
+            revalidations.increment();
+            HttpFields cachedHeaders = cached.getHeaders();
+            request.headers(headers ->
+            {
+                String etag = cachedHeaders.get(HttpHeader.ETAG);
+                if (etag != null)
+                    headers.put(HttpHeader.IF_NONE_MATCH, etag);
+                String lastModified = cachedHeaders.get(HttpHeader.LAST_MODIFIED);
+                if (lastModified != null)
+                    headers.put(HttpHeader.IF_MODIFIED_SINCE, lastModified);
+            });
+            chain.proceed(request, new RevalidatingListener(listener, request, key, requestHeaders, requestDirectives, requestTime, cached));
+            return;
+        }
+
+        misses.increment();
+        if (get)
+            chain.proceed(request, new StoringListener(listener, key, requestHeaders, requestDirectives, requestTime));
+        else
+            chain.proceed(request, listener);
+    }
+
+    /**
+     * @param cached the stored response
+     * @param requestDirectives the request cache directives
+     * @param now the current time
+     * @return whether the stored response can be used without revalidation
+     */
+    private boolean isServable(CachedResponse cached, CacheDirectives requestDirectives, long now)
+    {
+        CacheDirectives responseDirectives = CacheDirectives.ofResponse(cached.getHeaders());
+        if (requestDirectives.has(CacheDirectives.NO_CACHE) || responseDirectives.has(CacheDirectives.NO_CACHE))
+            return false;
+
+        long lifetime = cached.getFreshnessLifetime(getHeuristicMaxAge());
+        long age = cached.getAge(now);
+        long maxAge = requestDirectives.getSeconds(CacheDirectives.MAX_AGE);
+        if (maxAge >= 0 && age > TimeUnit.SECONDS.toMillis(maxAge))
+            return false;
+        long minFresh = requestDirectives.getSeconds(CacheDirectives.MIN_FRESH);
+        if (minFresh >= 0)
+            age += TimeUnit.SECONDS.toMillis(minFresh);
+        if (age < lifetime)
+            return true;
+
+        // Stale responses may be used only if the request allows it.
+        if (responseDirectives.has(CacheDirectives.MUST_REVALIDATE) || !requestDirectives.has(CacheDirectives.MAX_STALE))
+            return false;
+        long maxStale = requestDirectives.getSeconds(CacheDirectives.MAX_STALE);
+        return maxStale < 0 || age - lifetime <= TimeUnit.SECONDS.toMillis(maxStale);
+    }
+
+    /**
+     * @param response the response to a {@code GET} request
+     * @param requestDirectives the request cache directives
+     * @return whether the response can be stored
+     */
+    private boolean isCacheable(Response response, CacheDirectives requestDirectives)
+    {
+        if (requestDirectives.has(CacheDirectives.NO_STORE))
+            return false;
+        HttpFields headers = response.getHeaders();
+        CacheDirectives responseDirectives = CacheDirectives.ofResponse(headers);
+        if (responseDirectives.has(CacheDirectives.NO_STORE))
+            return false;
+        if (headers.getCSV(HttpHeader.VARY, false).contains("*"))
+            return false;
+        int status = response.getStatus();
+        if (responseDirectives.has(CacheDirectives.MAX_AGE) || responseDirectives.has(CacheDirectives.PUBLIC) || headers.contains(HttpHeader.EXPIRES))
+            return EXPLICITLY_CACHEABLE.contains(status);
+        return HEURISTICALLY_CACHEABLE.contains(status) &&
+            (headers.contains(HttpHeader.ETAG) || headers.contains(HttpHeader.LAST_MODIFIED));
+    }
+
+    private boolean hasValidators(CachedResponse cached)
+    {
+        HttpFields headers = cached.getHeaders();
+        return headers.contains(HttpHeader.ETAG) || headers.contains(HttpHeader.LAST_MODIFIED);
+    }
+
+    private void respond(Request request, Response.Listener listener, CachedResponse cached, long now, boolean content)
+    {
+        HttpFields.Mutable headers = HttpFields.build(cached.getHeaders());
+        headers.put(HttpHeader.AGE, String.valueOf(TimeUnit.MILLISECONDS.toSeconds(cached.getAge(now))));
+        Interceptor.respond(request, listener, cached.getStatus(), headers, content ? ByteBuffer.wrap(cached.getContent()) : null);
+    }
+
+    private void store(String key, HttpFields requestHeaders, Response response, byte[] content, long requestTime)
+    {
+        HttpFields.Mutable headers = HttpFields.build(response.getHeaders());
+        // The stored content is the one already decoded by HttpClient.
+        if (isDecoded(headers))
+            headers.remove(HttpHeader.CONTENT_ENCODING);
+        headers.remove(HttpHeader.TRANSFER_ENCODING);
+        headers.put(HttpHeader.CONTENT_LENGTH, String.valueOf(content.length));
+
+        Map<String, String> varyHeaders = new LinkedHashMap<>();
+        for (String name : response.getHeaders().getCSV(HttpHeader.VARY, false))
+        {
+            varyHeaders.put(name.toLowerCase(Locale.ENGLISH), CachedResponse.varyValue(requestHeaders, name));
+        }
+
+        CachedResponse cached = new CachedResponse(response.getStatus(), headers, content, varyHeaders, requestTime, System.currentTimeMillis());
+        if (LOG.isDebugEnabled())
+            LOG.debug("Storing {} for {}", cached, key);
+        storage.put(key, cached);
+        stores.increment();
+    }
+
+    private boolean isDecoded(HttpFields headers)
+    {
+        List<String> encodings = headers.getCSV(HttpHeader.CONTENT_ENCODING, false);
+        if (encodings.isEmpty())
+            return false;
+        for (ContentDecoder.Factory factory : httpClient.getContentDecoderFactories())
+        {
+            for (String encoding : encodings)
+            {
+                if (factory.getEncoding().equalsIgnoreCase(encoding))
+                    return true;
+            }
+        }
+        return false;
+    }
+
+    private void invalidate(URI uri, String location)
+    {
+        if (location == null)
+            return;
+        try
+        {
+            URI target = uri.resolve(location);
+            // Only invalidate URIs with the same origin as the request.
+            if (Objects.equals(keyOrigin(uri), keyOrigin(target)))
+                invalidate(target);
+        }
+        catch (IllegalArgumentException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid location {}", location, x);
+        }
+    }
+
+    private void invalidate(URI uri)
+    {
+        String key = keyOf(uri);
+        if (storage.get(key) == null)
+            return;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Invalidating {}", key);
+        storage.remove(key);
+        invalidations.increment();
+    }
+
+    private static String keyOrigin(URI uri)
+    {
+        String scheme = uri.getScheme();
+        String host = uri.getHost();
+        if (scheme == null || host == null)
+            return null;
+        scheme = scheme.toLowerCase(Locale.ENGLISH);
+        return scheme + "://" + host.toLowerCase(Locale.ENGLISH) + ":" + HttpClient.normalizePort(scheme, uri.getPort());
+    }
+
+    /**
+     * @param uri the request URI
+     * @return the cache key for the given URI, made of the origin, path and query
+     */
+    protected String keyOf(URI uri)
+    {
+        String path = uri.getRawPath();
+        if (path == null || path.isEmpty())
+            path = "/";
+        String query = uri.getRawQuery();
+        return keyOrigin(uri) + path + (query == null ? "" : "?" + query);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), storage);
+    }
+
+    /**
+     * <p>Accumulates the content of cacheable responses and stores them when complete.</p>
+     */
+    private class StoringListener extends ListenerWrapper
+    {
+        private final String key;
+        private final HttpFields requestHeaders;
+        private final CacheDirectives requestDirectives;
+        private final long requestTime;
+        private ByteArrayOutputStream content;
+
+        private StoringListener(Response.Listener wrapped, String key, HttpFields requestHeaders, CacheDirectives requestDirectives, long requestTime)
+        {
+            super(wrapped);
+            this.key = key;
+            this.requestHeaders = requestHeaders;
+            this.requestDirectives = requestDirectives;
+            this.requestTime = requestTime;
+        }
+
+        @Override
+        public void onHeaders(Response response)
+        {
+            if (isCacheable(response, requestDirectives))
+            {
+                long contentLength = response.getHeaders().getLongField(HttpHeader.CONTENT_LENGTH);
+                if (contentLength <= getMaxContentLength())
+                    content = new ByteArrayOutputStream(contentLength > 0 ? (int)contentLength : 1024);
+            }
+            super.onHeaders(response);
+        }
+
+        @Override
+        public void onContent(Response response, LongConsumer demand, ByteBuffer buffer, Callback callback)
+        {
+            if (content != null)
+            {
+                int length = buffer.remaining();
+                if (content.size() + length > getMaxContentLength())
+                {
+                    content = null;
+                }
+                else
+                {
+                    byte[] bytes = new byte[length];
+                    buffer.slice().get(bytes);
+                    content.write(bytes, 0, length);
+                }
+            }
+            super.onContent(response, demand, buffer, callback);
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            if (content != null)
+            {
+                try
+                {
+                    store(key, requestHeaders, response, content.toByteArray(), requestTime);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("failure storing {}", key, x);
+                }
+                content = null;
+            }
+            super.onSuccess(response);
+        }
+    }
+
+    /**
+     * <p>Handles the response to a revalidation request; a {@code 304} response
+     * is not forwarded, and the refreshed stored response is forwarded instead.</p>
+     */
+    private class RevalidatingListener extends StoringListener
+    {
+        private final Request request;
+        private final String key;
+        private final long requestTime;
+        private final CachedResponse cached;
+        private boolean notModified;
+
+        private RevalidatingListener(Response.Listener wrapped, Request request, String key, HttpFields requestHeaders, CacheDirectives requestDirectives, long requestTime, CachedResponse cached)
+        {
+            super(wrapped, key, requestHeaders, requestDirectives, requestTime);
+            this.request = request;
+            this.key = key;
+            this.requestTime = requestTime;
+            this.cached = cached;
+        }
+
+        @Override
+        public void onBegin(Response response)
+        {
+            notModified = response.getStatus() == HttpStatus.NOT_MODIFIED_304;
+            if (!notModified)
+                super.onBegin(response);
+        }
+
+        @Override
+        public boolean onHeader(Response response, HttpField field)
+        {
+            return notModified || super.onHeader(response, field);
+        }
+
+        @Override
+        public void onHeaders(Response response)
+        {
+            if (!notModified)
+                super.onHeaders(response);
+        }
+
+        @Override
+        public void onBeforeContent(Response response, LongConsumer demand)
+        {
+            if (notModified)
+                demand.accept(1);
+            else
+                super.onBeforeContent(response, demand);
+        }
+
+        @Override
+        public void onContent(Response response, LongConsumer demand, ByteBuffer buffer, Callback callback)
+        {
+            if (notModified)
+            {
+                callback.succeeded();
+                demand.accept(1);
+            }
+            else
+            {
+                super.onContent(response, demand, buffer, callback);
+            }
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            if (!notModified)
+            {
+                super.onSuccess(response);
+                return;
+            }
+
+            long now = System.currentTimeMillis();
+            CachedResponse revalidated = cached.revalidate(response.getHeaders(), requestTime, now);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Revalidated {} for {}", revalidated, key);
+            storage.put(key, revalidated);
+            validations.increment();
+            respond(request, getWrapped(), revalidated, now, true);
+        }
+
+        @Override
+        public void onFailure(Response response, Throwable failure)
+        {
+            if (notModified)
+                Interceptor.fail(request, getWrapped(), failure);
+            else
+                super.onFailure(response, failure);
+        }
+
+        @Override
+        public void onComplete(Result result)
+        {
+            // The wrapped listener has already been completed for 304 responses.
+            if (!notModified)
+                super.onComplete(result);
+        }
+    }
+
+    /**
+     * <p>Invalidates the stored responses after successful responses to unsafe requests.</p>
+     */
+    private class InvalidatingListener extends ListenerWrapper
+    {
+        private final URI uri;
+
+        private InvalidatingListener(Response.Listener wrapped, URI uri)
+        {
+            super(wrapped);
+            this.uri = uri;
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            int status = response.getStatus();
+            if (HttpStatus.isSuccess(status) || HttpStatus.isRedirection(status))
+            {
+                invalidate(uri);
+                HttpFields headers = response.getHeaders();
+                invalidate(uri, headers.get(HttpHeader.LOCATION));
+                invalidate(uri, headers.get(HttpHeader.CONTENT_LOCATION));
+            }
+            super.onSuccess(response);
+        }
+    }
+}
@@ -0,0 +1,215 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.io.BufferedInputStream;
+import java.io.BufferedOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.DirectoryStream;
+import java.nio.file.Files;
+import java.nio.file.NoSuchFileException;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link CacheStorage} that stores responses in files in a directory.</p>
+ * <p>Each response is stored in its own file, named after the SHA-256 hash of
+ * the cache key, so that responses survive restarts of the application.
+ * Files are written to a temporary file and then atomically moved, so that
+ * concurrent readers never see partially written responses.</p>
+ * <p>Failures to read or write files are logged and treated as cache misses.</p>
+ */
+@ManagedObject("A file cache storage")
+public class FileCacheStorage implements CacheStorage
+{
+    private static final Logger LOG = LoggerFactory.getLogger(FileCacheStorage.class);
+    private static final int VERSION = 1;
+    private static final String SUFFIX = ".cache";
+
+    private final Path directory;
+
+    /**
+     * @param directory the directory where responses are stored, created if it does not exist
+     * @throws IOException if the directory cannot be created
+     */
+    public FileCacheStorage(Path directory) throws IOException
+    {
+        this.directory = Files.createDirectories(directory);
+    }
+
+    @ManagedAttribute("The directory where responses are stored")
+    public Path getDirectory()
+    {
+        return directory;
+    }
+
+    @Override
+    public CachedResponse get(String key)
+    {
+        Path path = pathOf(key);
+        try (DataInputStream input = new DataInputStream(new BufferedInputStream(Files.newInputStream(path))))
+        {
+            if (input.readInt() != VERSION)
+                return null;
+            // Protect against hash collisions.
+            if (!key.equals(input.readUTF()))
+                return null;
+            int status = input.readInt();
+            long requestTime = input.readLong();
+            long responseTime = input.readLong();
+            HttpFields.Mutable headers = HttpFields.build();
+            int count = input.readInt();
+            for (int i = 0; i < count; ++i)
+            {
+                headers.add(input.readUTF(), input.readUTF());
+            }
+            Map<String, String> varyHeaders = new LinkedHashMap<>();
+            count = input.readInt();
+            for (int i = 0; i < count; ++i)
+            {
+                varyHeaders.put(input.readUTF(), input.readUTF());
+            }
+            byte[] content = new byte[input.readInt()];
+            input.readFully(content);
+            return new CachedResponse(status, headers, content, varyHeaders, requestTime, responseTime);
+        }
+        catch (NoSuchFileException x)
+        {
+            return null;
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not read {} from {}", key, path, x);
+            return null;
+        }
+    }
+
+    @Override
+    public void put(String key, CachedResponse response)
+    {
+        Path path = pathOf(key);
+        Path temp = null;
+        try
+        {
+            temp = Files.createTempFile(directory, path.getFileName().toString(), ".tmp");
+            try (DataOutputStream output = new DataOutputStream(new BufferedOutputStream(Files.newOutputStream(temp))))
+            {
+                output.writeInt(VERSION);
+                output.writeUTF(key);
+                output.writeInt(response.getStatus());
+                output.writeLong(response.getRequestTime());
+                output.writeLong(response.getResponseTime());
+                HttpFields headers = response.getHeaders();
+                output.writeInt(headers.size());
+                for (HttpField field : headers)
+                {
+                    output.writeUTF(field.getName());
+                    output.writeUTF(field.getValue() == null ? "" : field.getValue());
+                }
+                Map<String, String> varyHeaders = response.getVaryHeaders();
+                output.writeInt(varyHeaders.size());
+                for (Map.Entry<String, String> entry : varyHeaders.entrySet())
+                {
+                    output.writeUTF(entry.getKey());
+                    output.writeUTF(entry.getValue());
+                }
+                byte[] content = response.getContent();
+                output.writeInt(content.length);
+                output.write(content);
+            }
+            Files.move(temp, path, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not write {} to {}", key, path, x);
+            delete(temp);
+        }
+    }
+
+    @Override
+    public void remove(String key)
+    {
+        delete(pathOf(key));
+    }
+
+    @Override
+    public void clear()
+    {
+        try (DirectoryStream<Path> paths = Files.newDirectoryStream(directory, "*" + SUFFIX))
+        {
+            for (Path path : paths)
+            {
+                delete(path);
+            }
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not clear {}", directory, x);
+        }
+    }
+
+    private Path pathOf(String key)
+    {
+        try
+        {
+            MessageDigest digest = MessageDigest.getInstance("SHA-256");
+            byte[] hash = digest.digest(key.getBytes(StandardCharsets.UTF_8));
+            return directory.resolve(StringUtil.toHexString(hash) + SUFFIX);
+        }
+        catch (NoSuchAlgorithmException x)
+        {
+            // SHA-256 is required to be supported by every JVM.
+            throw new IllegalStateException(x);
+        }
+    }
+
+    private void delete(Path path)
+    {
+        if (path == null)
+            return;
+        try
+        {
+            Files.deleteIfExists(path);
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not delete {}", path, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), directory);
+    }
+}
@@ -0,0 +1,144 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.util.Iterator;
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A {@link CacheStorage} that stores responses in the heap.</p>
+ * <p>When either the max number of entries or the max number of content
+ * bytes is exceeded, the least recently used entries are evicted.</p>
+ */
+@ManagedObject("A heap cache storage with LRU eviction")
+public class HeapCacheStorage implements CacheStorage
+{
+    private final AutoLock lock = new AutoLock();
+    private final Map<String, CachedResponse> entries = new LinkedHashMap<>(16, 0.75F, true);
+    private final int maxEntries;
+    private final long maxContentBytes;
+    private long contentBytes;
+
+    public HeapCacheStorage()
+    {
+        this(1024, 64 * 1024 * 1024);
+    }
+
+    /**
+     * @param maxEntries the max number of entries
+     * @param maxContentBytes the max number of content bytes of all entries
+     */
+    public HeapCacheStorage(int maxEntries, long maxContentBytes)
+    {
+        this.maxEntries = maxEntries;
+        this.maxContentBytes = maxContentBytes;
+    }
+
+    @ManagedAttribute("The max number of entries")
+    public int getMaxEntries()
+    {
+        return maxEntries;
+    }
+
+    @ManagedAttribute("The max number of content bytes of all entries")
+    public long getMaxContentBytes()
+    {
+        return maxContentBytes;
+    }
+
+    @ManagedAttribute("The number of entries")
+    public int getSize()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return entries.size();
+        }
+    }
+
+    @ManagedAttribute("The number of content bytes of all entries")
+    public long getContentBytes()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return contentBytes;
+        }
+    }
+
+    @Override
+    public CachedResponse get(String key)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return entries.get(key);
+        }
+    }
+
+    @Override
+    public void put(String key, CachedResponse response)
+    {
+        int length = response.getContent().length;
+        if (length > maxContentBytes)
+            return;
+        try (AutoLock ignored = lock.lock())
+        {
+            CachedResponse existing = entries.put(key, response);
+            if (existing != null)
+                contentBytes -= existing.getContent().length;
+            contentBytes += length;
+
+            Iterator<CachedResponse> iterator = entries.values().iterator();
+            while (iterator.hasNext() && (entries.size() > maxEntries || contentBytes > maxContentBytes))
+            {
+                CachedResponse eldest = iterator.next();
+                contentBytes -= eldest.getContent().length;
+                iterator.remove();
+            }
+        }
+    }
+
+    @Override
+    public void remove(String key)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            CachedResponse existing = entries.remove(key);
+            if (existing != null)
+                contentBytes -= existing.getContent().length;
+        }
+    }
+
+    @Override
+    public void clear()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            entries.clear();
+            contentBytes = 0;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return String.format("%s@%x[entries=%d/%d,bytes=%d/%d]", getClass().getSimpleName(), hashCode(), entries.size(), maxEntries, contentBytes, maxContentBytes);
+        }
+    }
+}
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.client.http;
 
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.LongAdder;
 
 import org.eclipse.jetty.client.HttpChannel;
@@ -127,6 +128,9 @@ public class HttpChannelOverHTTP extends HttpChannel
             }
         }
 
+        if (closeReason == null && !isTunnel && getHttpDestination().getHttpClient().isKeepAliveHintsEnabled())
+            closeReason = keepAlive(responseHeaders);
+
         if (closeReason != null)
         {
             if (LOG.isDebugEnabled())
@@ -145,6 +149,42 @@ public class HttpChannelOverHTTP extends HttpChannel
         }
     }
 
+    private String keepAlive(HttpFields responseHeaders)
+    {
+        for (String parameter : responseHeaders.getCSV(HttpHeader.KEEP_ALIVE, false))
+        {
+            int equals = parameter.indexOf('=');
+            if (equals < 0)
+                continue;
+            String name = parameter.substring(0, equals).trim();
+            long value;
+            try
+            {
+                value = Long.parseLong(parameter.substring(equals + 1).trim());
+            }
+            catch (NumberFormatException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Ignoring invalid Keep-Alive parameter {}", parameter, x);
+                continue;
+            }
+
+            if ("max".equalsIgnoreCase(name))
+            {
+                // The server will not serve further requests.
+                if (value <= 0)
+                    return "keep-alive max";
+            }
+            else if ("timeout".equalsIgnoreCase(name))
+            {
+                if (value <= 0)
+                    return "keep-alive timeout";
+                connection.onKeepAliveTimeout(TimeUnit.SECONDS.toMillis(value));
+            }
+        }
+        return null;
+    }
+
     protected long getMessagesIn()
     {
         return receiver.getMessagesIn();
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.client.http;
 
+import java.net.SocketAddress;
 import java.nio.ByteBuffer;
 import java.nio.channels.AsynchronousCloseException;
 import java.util.Collections;
@@ -23,6 +24,7 @@ import java.util.concurrent.TimeoutException;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.LongAdder;
+import javax.net.ssl.SSLSession;
 
 import org.eclipse.jetty.client.HttpChannel;
 import org.eclipse.jetty.client.HttpClientTransport;
@@ -42,6 +44,9 @@ import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpVersion;
 import org.eclipse.jetty.io.AbstractConnection;
 import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.io.ssl.SslHandshakeListener;
+import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Attachable;
 import org.eclipse.jetty.util.Promise;
 import org.eclipse.jetty.util.thread.Sweeper;
@@ -53,6 +58,7 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
     private static final Logger LOG = LoggerFactory.getLogger(HttpConnectionOverHTTP.class);
 
     private final AtomicBoolean closed = new AtomicBoolean();
+    private final AtomicBoolean opened = new AtomicBoolean();
     private final AtomicInteger sweeps = new AtomicInteger();
     private final Promise<Connection> promise;
     private final Delegate delegate;
@@ -150,8 +156,55 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
     public void onOpen()
     {
         super.onOpen();
+        SslConnection sslConnection = null;
+        EndPoint endPoint = getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+        {
+            // Report the connection as opened only when the TLS handshake
+            // is complete, so that pre-created connections are ready for
+            // use, like it happens for negotiated protocols.
+            sslConnection = ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection();
+            if (sslConnection.isHandshakeComplete())
+                sslConnection = null;
+            else
+                sslConnection.addHandshakeListener(new HandshakeListener());
+        }
         fillInterested();
-        promise.succeeded(this);
+        if (sslConnection == null)
+        {
+            opened(null);
+            return;
+        }
+        try
+        {
+            // Start the TLS handshake.
+            endPoint.flush(BufferUtil.EMPTY_BUFFER);
+        }
+        catch (Throwable x)
+        {
+            opened(x);
+            close(x);
+        }
+    }
+
+    private void opened(Throwable failure)
+    {
+        if (opened.compareAndSet(false, true))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Opened {}", this, failure);
+            if (failure == null)
+                promise.succeeded(this);
+            else
+                promise.failed(failure);
+        }
+    }
+
+    @Override
+    public void onClose(Throwable cause)
+    {
+        super.onClose(cause);
+        opened(cause == null ? new AsynchronousCloseException() : cause);
     }
 
     @Override
@@ -160,6 +213,33 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
         return closed.get();
     }
 
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return getEndPoint().getLocalSocketAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return getEndPoint().getRemoteSocketAddress();
+    }
+
+    @Override
+    public String getProtocol()
+    {
+        return "http/1.1";
+    }
+
+    @Override
+    public SSLSession getSSLSession()
+    {
+        EndPoint endPoint = getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+            return ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getSSLEngine().getSession();
+        return null;
+    }
+
     @Override
     public void setAttachment(Object obj)
     {
@@ -211,6 +291,19 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
         }
     }
 
+    void onKeepAliveTimeout(long keepAliveTimeout)
+    {
+        // Expire the connection before the server does, so that requests are
+        // not sent on a connection that the server is about to close.
+        long timeout = keepAliveTimeout - Math.min(keepAliveTimeout / 2, 1000);
+        if (idleTimeout <= 0 || timeout < idleTimeout)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Keep-Alive timeout {} ms, idle timeout {} ms on {}", keepAliveTimeout, timeout, this);
+            idleTimeout = timeout;
+        }
+    }
+
     public void release()
     {
         // Restore idle timeout
@@ -271,6 +364,21 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
             channel);
     }
 
+    private class HandshakeListener implements SslHandshakeListener
+    {
+        @Override
+        public void handshakeSucceeded(Event event)
+        {
+            opened(null);
+        }
+
+        @Override
+        public void handshakeFailed(Event event, Throwable failure)
+        {
+            opened(failure);
+        }
+    }
+
     private class Delegate extends HttpConnection
     {
         private Delegate(HttpDestination destination)
@@ -348,6 +456,18 @@ public class HttpConnectionOverHTTP extends AbstractConnection implements IConne
             return HttpConnectionOverHTTP.this.isClosed();
         }
 
+        @Override
+        public SocketAddress getLocalSocketAddress()
+        {
+            return HttpConnectionOverHTTP.this.getLocalSocketAddress();
+        }
+
+        @Override
+        public SocketAddress getRemoteSocketAddress()
+        {
+            return HttpConnectionOverHTTP.this.getRemoteSocketAddress();
+        }
+
         @Override
         public String toString()
         {
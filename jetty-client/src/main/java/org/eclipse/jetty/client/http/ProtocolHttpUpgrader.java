@@ -70,7 +70,7 @@ public class ProtocolHttpUpgrader implements HttpUpgrader
                 HttpClientTransportDynamic dynamicTransport = (HttpClientTransportDynamic)transport;
 
                 Origin origin = destination.getOrigin();
-                Origin newOrigin = new Origin(origin.getScheme(), origin.getAddress(), origin.getTag(), new Origin.Protocol(List.of(protocol), false));
+                Origin newOrigin = new Origin(origin.getScheme(), origin.getAddress(), origin.getTag(), new Origin.Protocol(List.of(protocol), false), origin.getUnixDomainPath(), origin.getProxy());
                 HttpDestination newDestination = httpClient.resolveDestination(newOrigin);
 
                 Map<String, Object> context = new HashMap<>();
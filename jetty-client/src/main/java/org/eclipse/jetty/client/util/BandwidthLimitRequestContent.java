@@ -0,0 +1,130 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.io.BandwidthLimiter;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Request.Content} that limits the upload throughput of another {@link Request.Content}.</p>
+ * <p>Each content buffer is accounted to the given {@link BandwidthLimiter} when
+ * it is produced, and the demand for the next content buffer is delayed until
+ * the limiter allows more bytes to be transferred.
+ * Because content buffers are not split, the throughput is more accurate if the
+ * size of the content buffers is small compared to the limiter burst.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * BandwidthLimiter limiter = new BandwidthLimiter(64 * 1024, 8 * 1024);
+ * Request.Content content = new PathRequestContent(path);
+ * httpClient.POST(uri)
+ *     .body(new BandwidthLimitRequestContent(content, limiter, httpClient.getScheduler()))
+ *     .send();
+ * }</pre>
+ *
+ * @see BandwidthLimitResponseListener
+ */
+public class BandwidthLimitRequestContent implements Request.Content
+{
+    private static final Logger LOG = LoggerFactory.getLogger(BandwidthLimitRequestContent.class);
+
+    private final Request.Content content;
+    private final BandwidthLimiter limiter;
+    private final Scheduler scheduler;
+
+    public BandwidthLimitRequestContent(Request.Content content, BandwidthLimiter limiter, Scheduler scheduler)
+    {
+        this.content = Objects.requireNonNull(content);
+        this.limiter = Objects.requireNonNull(limiter);
+        this.scheduler = Objects.requireNonNull(scheduler);
+    }
+
+    @Override
+    public String getContentType()
+    {
+        return content.getContentType();
+    }
+
+    @Override
+    public long getLength()
+    {
+        return content.getLength();
+    }
+
+    @Override
+    public boolean isReproducible()
+    {
+        return content.isReproducible();
+    }
+
+    @Override
+    public Subscription subscribe(Consumer consumer, boolean emitInitialContent)
+    {
+        Subscription subscription = content.subscribe((buffer, last, callback) ->
+        {
+            limiter.reserve(buffer.remaining());
+            consumer.onContent(buffer, last, callback);
+        }, emitInitialContent);
+        return new LimitedSubscription(subscription);
+    }
+
+    @Override
+    public void fail(Throwable failure)
+    {
+        content.fail(failure);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), limiter, content);
+    }
+
+    private class LimitedSubscription implements Subscription
+    {
+        private final Subscription subscription;
+
+        private LimitedSubscription(Subscription subscription)
+        {
+            this.subscription = subscription;
+        }
+
+        @Override
+        public void demand()
+        {
+            long delay = limiter.getDelay();
+            if (delay > 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Delaying demand by {} ns for {}", delay, BandwidthLimitRequestContent.this);
+                scheduler.schedule(subscription::demand, delay, TimeUnit.NANOSECONDS);
+            }
+            else
+            {
+                subscription.demand();
+            }
+        }
+
+        @Override
+        public void fail(Throwable failure)
+        {
+            subscription.fail(failure);
+        }
+    }
+}
@@ -0,0 +1,81 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.nio.ByteBuffer;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.io.BandwidthLimiter;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Response.DemandedContentListener} that limits the download throughput of a response.</p>
+ * <p>Each response content buffer is accounted to the given {@link BandwidthLimiter},
+ * and the demand for the next content buffer is delayed until the limiter allows
+ * more bytes to be transferred.
+ * Since the demand of all the content listeners of a response is aggregated,
+ * other listeners receive the response content at the limited rate too.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * BandwidthLimiter limiter = new BandwidthLimiter(64 * 1024, 8 * 1024);
+ * ContentResponse response = httpClient.newRequest(uri)
+ *     .onResponseContentDemanded(new BandwidthLimitResponseListener(limiter, httpClient.getScheduler()))
+ *     .send();
+ * }</pre>
+ *
+ * @see BandwidthLimitRequestContent
+ */
+public class BandwidthLimitResponseListener implements Response.DemandedContentListener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(BandwidthLimitResponseListener.class);
+
+    private final BandwidthLimiter limiter;
+    private final Scheduler scheduler;
+
+    public BandwidthLimitResponseListener(BandwidthLimiter limiter, Scheduler scheduler)
+    {
+        this.limiter = Objects.requireNonNull(limiter);
+        this.scheduler = Objects.requireNonNull(scheduler);
+    }
+
+    @Override
+    public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+    {
+        limiter.reserve(content.remaining());
+        callback.succeeded();
+        long delay = limiter.getDelay();
+        if (delay > 0)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Delaying demand by {} ns for {}", delay, response);
+            scheduler.schedule(() -> demand.accept(1), delay, TimeUnit.NANOSECONDS);
+        }
+        else
+        {
+            demand.accept(1);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), limiter);
+    }
+}
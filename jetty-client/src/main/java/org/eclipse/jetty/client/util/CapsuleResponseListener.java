@@ -0,0 +1,115 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.util.Objects;
+import java.util.concurrent.CompletableFuture;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.HttpResponseException;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.CapsuleConnection;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.io.EndPoint;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Response.Listener} for the response to an Extended CONNECT request whose
+ * tunnel carries <a href="https://datatracker.ietf.org/doc/html/rfc9297">capsules</a>.</p>
+ * <p>When the successful response headers arrive, a {@link CapsuleConnection} is installed
+ * on the tunnel: the {@link CapsuleConnection.Listener} is notified of the capsules sent
+ * by the server, and capsules are sent to the server with the connection returned by
+ * {@link #getConnection()}.
+ * A non successful response fails the future with a {@link HttpResponseException}.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * CapsuleResponseListener listener = new CapsuleResponseListener(httpClient, (type, value) -> process(type, value), 65536);
+ * Request request = httpClient.newRequest(uri)
+ *     .method(HttpMethod.CONNECT)
+ *     .headers(headers -> headers.put("Capsule-Protocol", "?1"));
+ * ((HttpRequest)request).upgradeProtocol("connect-udp");
+ * request.send(listener);
+ * CapsuleConnection connection = listener.getConnection().get(5, TimeUnit.SECONDS);
+ * connection.send(type, value, callback);
+ * }</pre>
+ */
+public class CapsuleResponseListener extends Response.Listener.Adapter
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CapsuleResponseListener.class);
+
+    private final CompletableFuture<CapsuleConnection> future = new CompletableFuture<>();
+    private final HttpClient client;
+    private final CapsuleConnection.Listener listener;
+    private final int maxCapsuleLength;
+    private volatile CapsuleConnection connection;
+
+    /**
+     * @param client the HttpClient that sends the request
+     * @param listener the listener notified of the capsules sent by the server
+     * @param maxCapsuleLength the max capsule value length, in bytes
+     */
+    public CapsuleResponseListener(HttpClient client, CapsuleConnection.Listener listener, int maxCapsuleLength)
+    {
+        this.client = Objects.requireNonNull(client);
+        this.listener = Objects.requireNonNull(listener);
+        this.maxCapsuleLength = maxCapsuleLength;
+    }
+
+    /**
+     * @return a future completed with the connection installed on the tunnel, or completed
+     * exceptionally if the tunnel could not be established
+     */
+    public CompletableFuture<CapsuleConnection> getConnection()
+    {
+        return future;
+    }
+
+    @Override
+    public void onHeaders(Response response)
+    {
+        HttpRequest request = (HttpRequest)response.getRequest();
+        EndPoint endPoint = (EndPoint)request.getConversation().getAttribute(EndPoint.class.getName());
+        if (HttpStatus.isSuccess(response.getStatus()) && endPoint != null)
+        {
+            CapsuleConnection connection = new CapsuleConnection(endPoint, client.getExecutor(), client.getByteBufferPool(), listener, maxCapsuleLength);
+            this.connection = connection;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Upgrading {} to {}", endPoint, connection);
+            endPoint.upgrade(connection);
+            future.complete(connection);
+        }
+        else
+        {
+            HttpResponseException failure = new HttpResponseException("Unexpected " + response + " for " + request, response);
+            response.abort(failure);
+            future.completeExceptionally(failure);
+        }
+    }
+
+    @Override
+    public void onComplete(Result result)
+    {
+        if (result.isFailed())
+        {
+            CapsuleConnection connection = this.connection;
+            if (connection == null)
+                future.completeExceptionally(result.getFailure());
+            else
+                connection.getEndPoint().close(result.getFailure());
+        }
+    }
+}
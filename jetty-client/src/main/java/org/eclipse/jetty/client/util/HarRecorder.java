@@ -0,0 +1,693 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.Writer;
+import java.net.HttpCookie;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.time.Instant;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.Deque;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.function.LongConsumer;
+import javax.net.ssl.SSLEngine;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.Interceptor;
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.io.ssl.SslHandshakeListener;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.Fields;
+import org.eclipse.jetty.util.Jetty;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>Records the request/response exchanges of a {@link HttpClient} in the
+ * <a href="http://www.softwareishard.com/blog/har-12-spec/">HTTP Archive (HAR) 1.2</a>
+ * format, so that they can be inspected with browser developer tools, or
+ * attached to bug reports.</p>
+ * <p>For every exchange, the request and response lines, headers and cookies
+ * are recorded, along with the request and response content up to the
+ * {@link #getMaxContentLength() max content length}, and the timings of the
+ * exchange: DNS resolution, TCP connect and TLS handshake for exchanges sent
+ * over new connections, time to send the request, time to the first response
+ * byte and time to receive the response content.</p>
+ * <p>A HarRecorder must be added as a bean to {@link HttpClient}, preferably
+ * before starting it, so that DNS resolutions can be timed:</p>
+ * <pre>{@code
+ * HarRecorder har = new HarRecorder(httpClient);
+ * httpClient.addBean(har);
+ * httpClient.start();
+ *
+ * // Send requests.
+ *
+ * har.writeTo(Path.of("exchanges.har"));
+ * }</pre>
+ * <p>Recording can be {@link #setRecording(boolean) toggled} at runtime, and only
+ * the most recent {@link #getMaxEntries() max entries} are retained.</p>
+ */
+@ManagedObject("Records HTTP exchanges in HAR format")
+public class HarRecorder extends AbstractLifeCycle implements Interceptor
+{
+    private final AutoLock lock = new AutoLock();
+    private final Deque<Map<String, Object>> entries = new ArrayDeque<>();
+    private final Map<InetSocketAddress, Resolution> resolutions = new ConcurrentHashMap<>();
+    private final Map<SocketAddress, ConnectionTimings> connections = new ConcurrentHashMap<>();
+    private final Map<SSLEngine, ConnectionTimings> handshakes = new ConcurrentHashMap<>();
+    private final ConnectionTimer connectionTimer = new ConnectionTimer();
+    private final HttpClient httpClient;
+    private SocketAddressResolver resolver;
+    private volatile boolean recording = true;
+    private int maxContentLength = 64 * 1024;
+    private int maxEntries = 1000;
+
+    /**
+     * @param httpClient the HttpClient whose exchanges are recorded
+     */
+    public HarRecorder(HttpClient httpClient)
+    {
+        this.httpClient = Objects.requireNonNull(httpClient);
+    }
+
+    /**
+     * @return whether exchanges are recorded
+     */
+    @ManagedAttribute("Whether exchanges are recorded")
+    public boolean isRecording()
+    {
+        return recording;
+    }
+
+    /**
+     * @param recording whether exchanges are recorded
+     */
+    public void setRecording(boolean recording)
+    {
+        this.recording = recording;
+    }
+
+    /**
+     * @return the max number of bytes of request and response content recorded per exchange
+     */
+    @ManagedAttribute("The max number of bytes of request and response content recorded per exchange")
+    public int getMaxContentLength()
+    {
+        return maxContentLength;
+    }
+
+    /**
+     * @param maxContentLength the max number of bytes of request and response content recorded per exchange
+     */
+    public void setMaxContentLength(int maxContentLength)
+    {
+        this.maxContentLength = maxContentLength;
+    }
+
+    /**
+     * @return the max number of exchanges retained
+     */
+    @ManagedAttribute("The max number of exchanges retained")
+    public int getMaxEntries()
+    {
+        return maxEntries;
+    }
+
+    /**
+     * @param maxEntries the max number of exchanges retained
+     */
+    public void setMaxEntries(int maxEntries)
+    {
+        this.maxEntries = maxEntries;
+    }
+
+    /**
+     * @return the number of exchanges retained
+     */
+    @ManagedAttribute("The number of exchanges retained")
+    public int getEntryCount()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return entries.size();
+        }
+    }
+
+    /**
+     * @return the recorded exchanges, as HAR {@code entry} objects
+     */
+    public List<Map<String, Object>> getEntries()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return new ArrayList<>(entries);
+        }
+    }
+
+    /**
+     * <p>Discards the recorded exchanges.</p>
+     */
+    @ManagedOperation(value = "Discards the recorded exchanges", impact = "ACTION")
+    public void clear()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            entries.clear();
+        }
+    }
+
+    /**
+     * @return the recorded exchanges as a HAR {@code log} object
+     */
+    public Map<String, Object> toHar()
+    {
+        Map<String, Object> creator = new LinkedHashMap<>();
+        creator.put("name", "Jetty HttpClient");
+        creator.put("version", Jetty.VERSION);
+        Map<String, Object> log = new LinkedHashMap<>();
+        log.put("version", "1.2");
+        log.put("creator", creator);
+        log.put("entries", getEntries());
+        return Map.of("log", log);
+    }
+
+    /**
+     * @return the recorded exchanges in HAR JSON format
+     */
+    @ManagedOperation(value = "Returns the recorded exchanges in HAR JSON format", impact = "INFO")
+    public String toJSON()
+    {
+        return new JSON().toJSON(toHar());
+    }
+
+    /**
+     * <p>Writes the recorded exchanges in HAR JSON format to the given file.</p>
+     *
+     * @param path the file to write
+     * @throws IOException if the file cannot be written
+     */
+    public void writeTo(Path path) throws IOException
+    {
+        try (Writer writer = Files.newBufferedWriter(path, StandardCharsets.UTF_8))
+        {
+            writer.write(toJSON());
+        }
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        SocketAddressResolver resolver = httpClient.getSocketAddressResolver();
+        if (resolver != null && !httpClient.isStarted())
+        {
+            this.resolver = resolver;
+            httpClient.setSocketAddressResolver(new TimingResolver(resolver));
+        }
+        httpClient.addBean(connectionTimer);
+        httpClient.addInterceptor(this);
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        httpClient.removeInterceptor(this);
+        httpClient.removeBean(connectionTimer);
+        if (resolver != null && !httpClient.isStarted())
+            httpClient.setSocketAddressResolver(resolver);
+        resolver = null;
+        resolutions.clear();
+        connections.clear();
+        handshakes.clear();
+    }
+
+    @Override
+    public void intercept(Request request, Response.Listener listener, Chain chain)
+    {
+        if (!isRecording())
+        {
+            chain.proceed(request, listener);
+            return;
+        }
+        Exchange exchange = new Exchange(request);
+        request.onRequestBegin(exchange::onRequestBegin)
+            .onRequestHeaders(exchange::onRequestHeaders)
+            .onRequestContent(exchange::onRequestContent)
+            .onRequestSuccess(exchange::onRequestSuccess);
+        chain.proceed(request, new RecordingListener(listener, exchange));
+    }
+
+    private void record(Map<String, Object> entry)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            entries.add(entry);
+            while (entries.size() > Math.max(0, maxEntries))
+            {
+                entries.poll();
+            }
+        }
+    }
+
+    private static double millis(long nanos)
+    {
+        return nanos < 0 ? -1 : nanos / (double)TimeUnit.MILLISECONDS.toNanos(1);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[recording=%b,entries=%d]", getClass().getSimpleName(), hashCode(), isRecording(), getEntryCount());
+    }
+
+    private static class Resolution
+    {
+        private final long dnsNanos;
+        private final long resolvedNanoTime;
+
+        private Resolution(long dnsNanos, long resolvedNanoTime)
+        {
+            this.dnsNanos = dnsNanos;
+            this.resolvedNanoTime = resolvedNanoTime;
+        }
+    }
+
+    private static class ConnectionTimings
+    {
+        private long dnsNanos = -1;
+        private long connectNanos = -1;
+        private long sslNanos = -1;
+        private long openedNanoTime;
+    }
+
+    private class TimingResolver implements SocketAddressResolver
+    {
+        private final SocketAddressResolver resolver;
+
+        private TimingResolver(SocketAddressResolver resolver)
+        {
+            this.resolver = resolver;
+        }
+
+        @Override
+        public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+        {
+            long begin = NanoTime.now();
+            resolver.resolve(host, port, new Promise.Wrapper<>(promise)
+            {
+                @Override
+                public void succeeded(List<InetSocketAddress> result)
+                {
+                    long end = NanoTime.now();
+                    Resolution resolution = new Resolution(NanoTime.elapsed(begin, end), end);
+                    for (InetSocketAddress address : result)
+                    {
+                        resolutions.put(address, resolution);
+                    }
+                    super.succeeded(result);
+                }
+            });
+        }
+    }
+
+    /**
+     * <p>Times the connect and the TLS handshake of new connections, correlated
+     * to exchanges via the local address of the connection.</p>
+     */
+    private class ConnectionTimer implements org.eclipse.jetty.io.Connection.Listener, SslHandshakeListener
+    {
+        @Override
+        public void onOpened(org.eclipse.jetty.io.Connection connection)
+        {
+            SocketAddress local = connection.getEndPoint().getLocalSocketAddress();
+            if (local == null)
+                return;
+            ConnectionTimings timings = connections.computeIfAbsent(local, key ->
+            {
+                ConnectionTimings result = new ConnectionTimings();
+                long now = NanoTime.now();
+                result.openedNanoTime = now;
+                SocketAddress remote = connection.getEndPoint().getRemoteSocketAddress();
+                Resolution resolution = remote == null ? null : resolutions.remove(remote);
+                if (resolution != null)
+                {
+                    result.dnsNanos = resolution.dnsNanos;
+                    result.connectNanos = NanoTime.elapsed(resolution.resolvedNanoTime, now);
+                }
+                return result;
+            });
+            if (connection instanceof SslConnection)
+                handshakes.put(((SslConnection)connection).getSSLEngine(), timings);
+        }
+
+        @Override
+        public void onClosed(org.eclipse.jetty.io.Connection connection)
+        {
+            SocketAddress local = connection.getEndPoint().getLocalSocketAddress();
+            if (local != null)
+                connections.remove(local);
+            if (connection instanceof SslConnection)
+                handshakes.remove(((SslConnection)connection).getSSLEngine());
+        }
+
+        @Override
+        public void handshakeSucceeded(Event event)
+        {
+            ConnectionTimings timings = handshakes.remove(event.getSSLEngine());
+            if (timings != null)
+                timings.sslNanos = NanoTime.since(timings.openedNanoTime);
+        }
+
+        @Override
+        public void handshakeFailed(Event event, Throwable failure)
+        {
+            handshakes.remove(event.getSSLEngine());
+        }
+    }
+
+    private class Exchange
+    {
+        private final Instant started = Instant.now();
+        private final long startNanoTime = NanoTime.now();
+        private final Request request;
+        private final ByteArrayOutputStream requestContent = new ByteArrayOutputStream();
+        private final ByteArrayOutputStream responseContent = new ByteArrayOutputStream();
+        private HttpFields requestHeaders = HttpFields.EMPTY;
+        private ConnectionTimings connectionTimings;
+        private String serverAddress;
+        private String connectionId;
+        private long requestContentSize;
+        private long responseContentSize;
+        private long beginNanoTime = -1;
+        private long sentNanoTime = -1;
+        private long responseNanoTime = -1;
+
+        private Exchange(Request request)
+        {
+            this.request = request;
+        }
+
+        private void onRequestBegin(Request request)
+        {
+            beginNanoTime = NanoTime.now();
+            Connection connection = request.getConnection();
+            if (connection == null)
+                return;
+            SocketAddress local = connection.getLocalSocketAddress();
+            if (local != null)
+            {
+                ConnectionTimings timings = connections.get(local);
+                // Only the first exchange over a connection pays the connection setup.
+                if (timings != null && connections.replace(local, timings, new ConnectionTimings()))
+                    connectionTimings = timings;
+                if (local instanceof InetSocketAddress)
+                    connectionId = String.valueOf(((InetSocketAddress)local).getPort());
+            }
+            SocketAddress remote = connection.getRemoteSocketAddress();
+            if (remote instanceof InetSocketAddress && ((InetSocketAddress)remote).getAddress() != null)
+                serverAddress = ((InetSocketAddress)remote).getAddress().getHostAddress();
+        }
+
+        private void onRequestHeaders(Request request)
+        {
+            requestHeaders = request.getHeaders().asImmutable();
+        }
+
+        private void onRequestContent(Request request, ByteBuffer content)
+        {
+            requestContentSize += content.remaining();
+            capture(requestContent, content);
+        }
+
+        private void onRequestSuccess(Request request)
+        {
+            sentNanoTime = NanoTime.now();
+        }
+
+        private void onResponseBegin()
+        {
+            responseNanoTime = NanoTime.now();
+        }
+
+        private void onResponseContent(ByteBuffer content)
+        {
+            responseContentSize += content.remaining();
+            capture(responseContent, content);
+        }
+
+        private void capture(ByteArrayOutputStream output, ByteBuffer content)
+        {
+            int length = Math.min(content.remaining(), getMaxContentLength() - output.size());
+            if (length > 0)
+                output.write(BufferUtil.toArray(content.slice().limit(length)), 0, length);
+        }
+
+        private void onComplete(Result result)
+        {
+            long endNanoTime = NanoTime.now();
+            Response response = result.getResponse();
+
+            Map<String, Object> timings = new LinkedHashMap<>();
+            long dns = connectionTimings == null ? -1 : connectionTimings.dnsNanos;
+            long connect = connectionTimings == null ? -1 : connectionTimings.connectNanos;
+            long ssl = connectionTimings == null ? -1 : connectionTimings.sslNanos;
+            long begin = beginNanoTime < 0 ? endNanoTime : beginNanoTime;
+            long blocked = Math.max(0, NanoTime.elapsed(startNanoTime, begin) - Math.max(0, dns) - Math.max(0, connect));
+            long sent = sentNanoTime < 0 ? begin : sentNanoTime;
+            long send = Math.max(0, NanoTime.elapsed(begin, sent));
+            long responded = responseNanoTime < 0 ? endNanoTime : responseNanoTime;
+            long wait = Math.max(0, NanoTime.elapsed(sent, responded));
+            long receive = Math.max(0, NanoTime.elapsed(responded, endNanoTime));
+            timings.put("blocked", millis(blocked));
+            timings.put("dns", millis(dns));
+            // In HAR, the connect time includes the TLS handshake time.
+            timings.put("connect", connect < 0 ? -1 : millis(connect + Math.max(0, ssl)));
+            timings.put("send", millis(send));
+            timings.put("wait", millis(wait));
+            timings.put("receive", millis(receive));
+            timings.put("ssl", millis(ssl));
+
+            Map<String, Object> entry = new LinkedHashMap<>();
+            entry.put("startedDateTime", started.toString());
+            entry.put("time", millis(blocked + Math.max(0, dns) + Math.max(0, connect) + Math.max(0, ssl) + send + wait + receive));
+            entry.put("request", toHarRequest());
+            entry.put("response", toHarResponse(response, result.getFailure()));
+            entry.put("cache", Map.of());
+            entry.put("timings", timings);
+            if (serverAddress != null)
+                entry.put("serverIPAddress", serverAddress);
+            if (connectionId != null)
+                entry.put("connection", connectionId);
+            if (result.isFailed())
+                entry.put("comment", String.valueOf(result.getFailure()));
+            record(entry);
+        }
+
+        private Map<String, Object> toHarRequest()
+        {
+            Map<String, Object> har = new LinkedHashMap<>();
+            har.put("method", request.getMethod());
+            har.put("url", request.getURI().toString());
+            har.put("httpVersion", request.getVersion().asString());
+            List<Map<String, Object>> cookies = new ArrayList<>();
+            for (HttpCookie cookie : request.getCookies())
+            {
+                cookies.add(toHarCookie(cookie));
+            }
+            har.put("cookies", cookies);
+            har.put("headers", toHarHeaders(requestHeaders));
+            List<Map<String, Object>> query = new ArrayList<>();
+            for (Fields.Field field : request.getParams())
+            {
+                for (String value : field.getValues())
+                {
+                    query.add(nameValue(field.getName(), value));
+                }
+            }
+            har.put("queryString", query);
+            if (requestContentSize > 0)
+            {
+                Map<String, Object> postData = new LinkedHashMap<>();
+                String mimeType = requestHeaders.get(HttpHeader.CONTENT_TYPE);
+                postData.put("mimeType", mimeType == null ? "" : mimeType);
+                postData.put("text", new String(requestContent.toByteArray(), StandardCharsets.UTF_8));
+                if (requestContentSize > requestContent.size())
+                    postData.put("comment", "truncated to " + requestContent.size() + " bytes");
+                har.put("postData", postData);
+            }
+            har.put("headersSize", -1);
+            har.put("bodySize", requestContentSize);
+            return har;
+        }
+
+        private Map<String, Object> toHarResponse(Response response, Throwable failure)
+        {
+            Map<String, Object> har = new LinkedHashMap<>();
+            HttpFields headers = response == null ? HttpFields.EMPTY : response.getHeaders();
+            int status = response == null ? 0 : response.getStatus();
+            har.put("status", status);
+            String reason = response == null ? null : response.getReason();
+            har.put("statusText", reason == null ? "" : reason);
+            har.put("httpVersion", response == null || response.getVersion() == null ? "" : response.getVersion().asString());
+            List<Map<String, Object>> cookies = new ArrayList<>();
+            for (String setCookie : headers.getValuesList(HttpHeader.SET_COOKIE))
+            {
+                try
+                {
+                    for (HttpCookie cookie : HttpCookie.parse(setCookie))
+                    {
+                        cookies.add(toHarCookie(cookie));
+                    }
+                }
+                catch (IllegalArgumentException x)
+                {
+                    // Ignore invalid cookies.
+                }
+            }
+            har.put("cookies", cookies);
+            har.put("headers", toHarHeaders(headers));
+
+            Map<String, Object> content = new LinkedHashMap<>();
+            content.put("size", responseContentSize);
+            String mimeType = headers.get(HttpHeader.CONTENT_TYPE);
+            content.put("mimeType", mimeType == null ? "" : mimeType);
+            if (responseContent.size() > 0)
+            {
+                byte[] bytes = responseContent.toByteArray();
+                if (isText(mimeType))
+                {
+                    content.put("text", new String(bytes, StandardCharsets.UTF_8));
+                }
+                else
+                {
+                    content.put("text", Base64.getEncoder().encodeToString(bytes));
+                    content.put("encoding", "base64");
+                }
+                if (responseContentSize > bytes.length)
+                    content.put("comment", "truncated to " + bytes.length + " bytes");
+            }
+            har.put("content", content);
+            String location = headers.get(HttpHeader.LOCATION);
+            har.put("redirectURL", location == null ? "" : location);
+            har.put("headersSize", -1);
+            har.put("bodySize", responseContentSize);
+            return har;
+        }
+
+        private boolean isText(String mimeType)
+        {
+            if (mimeType == null)
+                return false;
+            String type = mimeType.toLowerCase(Locale.ENGLISH);
+            return type.startsWith("text/") ||
+                type.contains("json") ||
+                type.contains("xml") ||
+                type.contains("javascript") ||
+                type.startsWith("application/x-www-form-urlencoded");
+        }
+
+        private List<Map<String, Object>> toHarHeaders(HttpFields headers)
+        {
+            List<Map<String, Object>> result = new ArrayList<>();
+            for (HttpField field : headers)
+            {
+                result.add(nameValue(field.getName(), field.getValue()));
+            }
+            return result;
+        }
+
+        private Map<String, Object> toHarCookie(HttpCookie cookie)
+        {
+            Map<String, Object> result = nameValue(cookie.getName(), cookie.getValue());
+            if (cookie.getPath() != null)
+                result.put("path", cookie.getPath());
+            if (cookie.getDomain() != null)
+                result.put("domain", cookie.getDomain());
+            result.put("httpOnly", cookie.isHttpOnly());
+            result.put("secure", cookie.getSecure());
+            return result;
+        }
+
+        private Map<String, Object> nameValue(String name, String value)
+        {
+            Map<String, Object> result = new LinkedHashMap<>();
+            result.put("name", name);
+            result.put("value", value == null ? "" : value);
+            return result;
+        }
+    }
+
+    private static class RecordingListener extends ListenerWrapper
+    {
+        private final Exchange exchange;
+
+        private RecordingListener(Response.Listener wrapped, Exchange exchange)
+        {
+            super(wrapped);
+            this.exchange = exchange;
+        }
+
+        @Override
+        public void onBegin(Response response)
+        {
+            exchange.onResponseBegin();
+            super.onBegin(response);
+        }
+
+        @Override
+        public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+        {
+            exchange.onResponseContent(content);
+            super.onContent(response, demand, content, callback);
+        }
+
+        @Override
+        public void onComplete(Result result)
+        {
+            exchange.onComplete(result);
+            super.onComplete(result);
+        }
+    }
+}
@@ -0,0 +1,164 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.file.AccessDeniedException;
+import java.nio.file.Files;
+import java.nio.file.NoSuchFileException;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IO;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Request.Content} for files that are memory-mapped rather than read into buffers.</p>
+ * <p>The file is mapped one window at a time, by default 64 MiB, and each window is
+ * emitted as slices of the configured chunk size, by default 64 KiB, so that very large
+ * files (even larger than 2 GiB) can be uploaded without copying their bytes into heap
+ * or pooled buffers.
+ * The mapped memory lives outside the Java heap and is released when the window
+ * becomes unreachable.</p>
+ * <p>Mapping is only convenient for large files; for small files {@link PathRequestContent}
+ * is typically more efficient.</p>
+ */
+public class MappedPathRequestContent extends AbstractRequestContent
+{
+    private static final Logger LOG = LoggerFactory.getLogger(MappedPathRequestContent.class);
+
+    private final Path filePath;
+    private final long fileSize;
+    private final int chunkSize;
+    private final long windowSize;
+
+    public MappedPathRequestContent(Path filePath) throws IOException
+    {
+        this("application/octet-stream", filePath);
+    }
+
+    public MappedPathRequestContent(String contentType, Path filePath) throws IOException
+    {
+        this(contentType, filePath, 64 * 1024, 64 * 1024 * 1024);
+    }
+
+    /**
+     * @param contentType the content type
+     * @param filePath the file to upload
+     * @param chunkSize the size of the content chunks emitted
+     * @param windowSize the size of the file region mapped at once
+     * @throws IOException if the file cannot be read
+     */
+    public MappedPathRequestContent(String contentType, Path filePath, int chunkSize, long windowSize) throws IOException
+    {
+        super(contentType);
+        if (chunkSize <= 0)
+            throw new IllegalArgumentException("Invalid chunk size " + chunkSize);
+        if (windowSize < chunkSize || windowSize > Integer.MAX_VALUE)
+            throw new IllegalArgumentException("Invalid window size " + windowSize);
+        if (!Files.isRegularFile(filePath))
+            throw new NoSuchFileException(filePath.toString());
+        if (!Files.isReadable(filePath))
+            throw new AccessDeniedException(filePath.toString());
+        this.filePath = filePath;
+        this.fileSize = Files.size(filePath);
+        this.chunkSize = chunkSize;
+        this.windowSize = windowSize;
+    }
+
+    @Override
+    public long getLength()
+    {
+        return fileSize;
+    }
+
+    @Override
+    public boolean isReproducible()
+    {
+        return true;
+    }
+
+    public int getChunkSize()
+    {
+        return chunkSize;
+    }
+
+    public long getWindowSize()
+    {
+        return windowSize;
+    }
+
+    @Override
+    protected Subscription newSubscription(Consumer consumer, boolean emitInitialContent)
+    {
+        return new SubscriptionImpl(consumer, emitInitialContent);
+    }
+
+    private class SubscriptionImpl extends AbstractSubscription
+    {
+        private FileChannel channel;
+        private ByteBuffer window;
+        private long position;
+
+        private SubscriptionImpl(Consumer consumer, boolean emitInitialContent)
+        {
+            super(consumer, emitInitialContent);
+        }
+
+        @Override
+        protected boolean produceContent(Producer producer) throws IOException
+        {
+            if (channel == null)
+            {
+                channel = FileChannel.open(filePath, StandardOpenOption.READ);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Opened file {}", filePath);
+            }
+
+            if (window == null || !window.hasRemaining())
+            {
+                long length = Math.min(windowSize, fileSize - position);
+                window = length == 0 ? ByteBuffer.allocate(0) : channel.map(FileChannel.MapMode.READ_ONLY, position, length);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Mapped {} bytes at {} from {}", length, position, filePath);
+            }
+
+            ByteBuffer chunk = window.slice();
+            int length = Math.min(chunkSize, chunk.remaining());
+            chunk.limit(length);
+            window.position(window.position() + length);
+            position += length;
+
+            boolean last = position == fileSize;
+            if (last)
+            {
+                IO.close(channel);
+                window = null;
+            }
+            return producer.produce(chunk, last, Callback.NOOP);
+        }
+
+        @Override
+        public void fail(Throwable failure)
+        {
+            super.fail(failure);
+            IO.close(channel);
+        }
+    }
+}
@@ -27,6 +27,7 @@ import java.util.concurrent.atomic.AtomicBoolean;
 import org.eclipse.jetty.client.AsyncContentProvider;
 import org.eclipse.jetty.client.Synchronizable;
 import org.eclipse.jetty.client.api.ContentProvider;
+import org.eclipse.jetty.http.ContentDisposition;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
@@ -232,10 +233,7 @@ public class MultiPartContentProvider extends AbstractTypedContentProvider imple
             try
             {
                 // Compute the Content-Disposition.
-                String contentDisposition = "Content-Disposition: form-data; name=\"" + name + "\"";
-                if (fileName != null)
-                    contentDisposition += "; filename=\"" + fileName + "\"";
-                contentDisposition += "\r\n";
+                String contentDisposition = "Content-Disposition: " + ContentDisposition.formData(name, fileName).asString() + "\r\n";
 
                 // Compute the Content-Type.
                 String contentType = fields == null ? null : fields.get(HttpHeader.CONTENT_TYPE);
@@ -20,10 +20,15 @@ import java.io.IOException;
 import java.nio.ByteBuffer;
 import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Base64;
 import java.util.List;
+import java.util.Objects;
 import java.util.Random;
+import java.util.function.Supplier;
 
 import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.ContentDisposition;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
@@ -53,12 +58,19 @@ import org.slf4j.LoggerFactory;
  *     &lt;input type="file" name="icon" /&gt;
  * &lt;/form&gt;
  * </pre>
+ * <p>The content of a part may also be supplied lazily, so that for example a file is only
+ * opened when the part is about to be sent; such parts have an unknown length and the
+ * request is sent with chunked encoding.</p>
+ * <p>A part may specify a {@code Content-Transfer-Encoding} header in its {@code fields}:
+ * the {@code 7bit}, {@code 8bit} and {@code binary} values send the content as is, while
+ * the {@code base64} value encodes the content while it is sent.</p>
  */
 public class MultiPartRequestContent extends AbstractRequestContent implements Closeable
 {
     private static final Logger LOG = LoggerFactory.getLogger(MultiPartRequestContent.class);
     private static final byte[] COLON_SPACE_BYTES = new byte[]{':', ' '};
     private static final byte[] CR_LF_BYTES = new byte[]{'\r', '\n'};
+    private static final String CONTENT_TRANSFER_ENCODING = "Content-Transfer-Encoding";
 
     private static String makeBoundary()
     {
@@ -124,6 +136,7 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
     {
         parts.stream()
             .map(part -> part.content)
+            .filter(Objects::nonNull)
             .forEach(content -> content.fail(failure));
     }
 
@@ -164,6 +177,36 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
         addPart(new Part(name, fileName, content, fields));
     }
 
+    /**
+     * <p>Adds a field part with the given {@code name} as field name, and the content
+     * produced by the given {@code content} supplier when the part is sent.</p>
+     *
+     * @param name the part name
+     * @param content the supplier of the part content, invoked when the part is sent
+     * @param fields the headers associated with this part
+     * @see #addFieldPart(String, Request.Content, HttpFields)
+     */
+    public void addFieldPart(String name, Supplier<Request.Content> content, HttpFields fields)
+    {
+        addPart(new Part(name, null, content, fields));
+    }
+
+    /**
+     * <p>Adds a file part with the given {@code name} as field name, the given
+     * {@code fileName} as file name, and the content produced by the given
+     * {@code content} supplier when the part is sent.</p>
+     *
+     * @param name the part name
+     * @param fileName the file name associated to this part
+     * @param content the supplier of the part content, invoked when the part is sent
+     * @param fields the headers associated with this part
+     * @see #addFilePart(String, String, Request.Content, HttpFields)
+     */
+    public void addFilePart(String name, String fileName, Supplier<Request.Content> content, HttpFields fields)
+    {
+        addPart(new Part(name, fileName, content, fields));
+    }
+
     private void addPart(Part part)
     {
         parts.add(part);
@@ -209,19 +252,67 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
     {
         private final String name;
         private final String fileName;
-        private final Request.Content content;
+        private final Supplier<Request.Content> supplier;
         private final HttpFields fields;
-        private final ByteBuffer headers;
-        private final long length;
+        private final boolean base64;
+        private Request.Content content;
+        private ByteBuffer headers;
+        private Base64Encoder encoder;
+        private long length;
 
         private Part(String name, String fileName, Request.Content content, HttpFields fields)
+        {
+            this(name, fileName, (Supplier<Request.Content>)null, fields);
+            open(content);
+            long contentLength = content.getLength();
+            this.length = contentLength < 0 ? -1 : headers.remaining() + (base64 ? Base64Encoder.encodedLength(contentLength) : contentLength);
+        }
+
+        private Part(String name, String fileName, Supplier<Request.Content> supplier, HttpFields fields)
         {
             this.name = name;
             this.fileName = fileName;
-            this.content = content;
+            this.supplier = supplier;
             this.fields = fields;
+            this.base64 = isBase64(fields == null ? null : fields.get(CONTENT_TRANSFER_ENCODING));
+            this.length = -1;
+        }
+
+        private static boolean isBase64(String transferEncoding)
+        {
+            if (transferEncoding == null)
+                return false;
+            switch (transferEncoding.trim().toLowerCase())
+            {
+                case "7bit":
+                case "8bit":
+                case "binary":
+                    return false;
+                case "base64":
+                    return true;
+                default:
+                    throw new IllegalArgumentException("Unsupported " + CONTENT_TRANSFER_ENCODING + ": " + transferEncoding);
+            }
+        }
+
+        private Request.Content open()
+        {
+            if (content == null)
+                open(Objects.requireNonNull(supplier.get(), "null part content"));
+            return content;
+        }
+
+        private void open(Request.Content content)
+        {
+            this.content = content;
             this.headers = headers();
-            this.length = content.getLength() < 0 ? -1 : headers.remaining() + content.getLength();
+            if (base64)
+                encoder = new Base64Encoder();
+        }
+
+        private ByteBuffer encode(ByteBuffer buffer, boolean last)
+        {
+            return encoder == null ? buffer : encoder.encode(buffer, last);
         }
 
         private ByteBuffer headers()
@@ -229,10 +320,7 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
             try
             {
                 // Compute the Content-Disposition.
-                String contentDisposition = "Content-Disposition: form-data; name=\"" + name + "\"";
-                if (fileName != null)
-                    contentDisposition += "; filename=\"" + fileName + "\"";
-                contentDisposition += "\r\n";
+                String contentDisposition = "Content-Disposition: " + ContentDisposition.formData(name, fileName).asString() + "\r\n";
 
                 // Compute the Content-Type.
                 String contentType = fields == null ? null : fields.get(HttpHeader.CONTENT_TYPE);
@@ -279,11 +367,56 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
                 hashCode(),
                 name,
                 fileName,
-                content.getLength(),
+                content == null ? -1 : content.getLength(),
                 fields);
         }
     }
 
+    /**
+     * <p>Encodes content in base64, in lines of 76 characters separated by CRLF,
+     * like {@link Base64#getMimeEncoder()} but across multiple buffers.</p>
+     */
+    private static class Base64Encoder
+    {
+        private static final int LINE_BYTES = 57;
+
+        private final byte[] pending = new byte[LINE_BYTES];
+        private int pendingLength;
+        private long lines;
+
+        private static long encodedLength(long length)
+        {
+            if (length == 0)
+                return 0;
+            long lines = (length + LINE_BYTES - 1) / LINE_BYTES;
+            return 4 * ((length + 2) / 3) + CR_LF_BYTES.length * (lines - 1);
+        }
+
+        private ByteBuffer encode(ByteBuffer buffer, boolean last)
+        {
+            ByteArrayOutputStream output = new ByteArrayOutputStream(buffer.remaining() * 4 / 3 + 4);
+            while (buffer.hasRemaining())
+            {
+                int length = Math.min(LINE_BYTES - pendingLength, buffer.remaining());
+                buffer.get(pending, pendingLength, length);
+                pendingLength += length;
+                if (pendingLength == LINE_BYTES)
+                    writeLine(output);
+            }
+            if (last && pendingLength > 0)
+                writeLine(output);
+            return ByteBuffer.wrap(output.toByteArray());
+        }
+
+        private void writeLine(ByteArrayOutputStream output)
+        {
+            if (lines++ > 0)
+                output.writeBytes(CR_LF_BYTES);
+            output.writeBytes(Base64.getEncoder().encode(Arrays.copyOf(pending, pendingLength)));
+            pendingLength = 0;
+        }
+    }
+
     private class SubscriptionImpl extends AbstractSubscription implements Consumer
     {
         private State state = State.FIRST_BOUNDARY;
@@ -321,7 +454,7 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
                 case HEADERS:
                 {
                     Part part = parts.get(index);
-                    Request.Content content = part.content;
+                    Request.Content content = part.open();
                     subscription = content.subscribe(this, true);
                     state = State.CONTENT;
                     buffer = part.headers.slice();
@@ -361,6 +494,7 @@ public class MultiPartRequestContent extends AbstractRequestContent implements C
         @Override
         public void onContent(ByteBuffer buffer, boolean last, Callback callback)
         {
+            buffer = parts.get(index).encode(buffer, last);
             if (last)
             {
                 ++index;
@@ -0,0 +1,585 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.EOFException;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.Map;
+import java.util.Objects;
+import java.util.TreeMap;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.http.ContentDisposition;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Response.Listener} that parses {@code multipart} response content,
+ * such as {@code multipart/byteranges} or {@code multipart/form-data}, into parts.</p>
+ * <p>The parts are notified to a {@link Listener} as they are parsed: first the
+ * {@link Listener#onPart(Part) part headers}, then the {@link Listener#onPartContent(Part, ByteBuffer, Callback)
+ * part content} in one or more chunks, and finally the {@link Listener#onPartEnd(Part) end of the part}.
+ * The part content is streamed: the content buffers are only valid until their callback is completed,
+ * and more response content is only read from the network once the callbacks of the content chunks
+ * previously notified are completed.</p>
+ * <p>Parts whose {@code Content-Type} is itself {@code multipart}, such as {@code multipart/mixed} parts
+ * nested in {@code multipart/form-data}, are parsed as well: their nested parts are notified between
+ * the {@link Listener#onPart(Part)} and {@link Listener#onPartEnd(Part)} events of the enclosing part,
+ * and have the enclosing part as {@link Part#getParent() parent}.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * httpClient.newRequest(uri)
+ *     .headers(headers -> headers.put(HttpHeader.RANGE, "bytes=0-99,200-299"))
+ *     .send(new MultiPartResponseListener(new MultiPartResponseListener.Listener()
+ *     {
+ *         @Override
+ *         public void onPartContent(Part part, ByteBuffer content, Callback callback)
+ *         {
+ *             // Write the bytes at offset part.getRangeFirst().
+ *             callback.succeeded();
+ *         }
+ *     }));
+ * }</pre>
+ *
+ * @see MultiPartRequestContent
+ */
+public class MultiPartResponseListener extends Response.Listener.Adapter
+{
+    private static final Logger LOG = LoggerFactory.getLogger(MultiPartResponseListener.class);
+    private static final byte[] CRLF = {'\r', '\n'};
+
+    private final Listener listener;
+    private int maxHeadersLength = 8 * 1024;
+    private Parser parser;
+
+    public MultiPartResponseListener(Listener listener)
+    {
+        this.listener = Objects.requireNonNull(listener);
+    }
+
+    /**
+     * @return the max length in bytes of the headers of a part
+     */
+    public int getMaxHeadersLength()
+    {
+        return maxHeadersLength;
+    }
+
+    /**
+     * @param maxHeadersLength the max length in bytes of the headers of a part
+     */
+    public void setMaxHeadersLength(int maxHeadersLength)
+    {
+        this.maxHeadersLength = maxHeadersLength;
+    }
+
+    @Override
+    public void onHeaders(Response response)
+    {
+        String contentType = response.getHeaders().get(HttpHeader.CONTENT_TYPE);
+        String boundary = boundary(contentType);
+        if (boundary == null)
+            response.abort(new IllegalStateException("Not a multipart response: " + response.getStatus() + " " + contentType));
+        else
+            parser = new Parser(boundary, null);
+    }
+
+    @Override
+    public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+    {
+        if (parser == null)
+        {
+            callback.succeeded();
+            return;
+        }
+
+        ChunkCallback chunk = new ChunkCallback(response, demand, callback);
+        try
+        {
+            parser.parse(content, chunk);
+            chunk.succeeded();
+        }
+        catch (Throwable x)
+        {
+            chunk.failed(x);
+        }
+    }
+
+    @Override
+    public void onSuccess(Response response)
+    {
+        if (parser == null || parser.isComplete())
+            listener.onSuccess(response);
+        else
+            listener.onFailure(response, new EOFException("Truncated multipart response"));
+    }
+
+    @Override
+    public void onFailure(Response response, Throwable failure)
+    {
+        listener.onFailure(response, failure);
+    }
+
+    private static String boundary(String contentType)
+    {
+        if (contentType == null)
+            return null;
+        Map<String, String> parameters = new TreeMap<>(String.CASE_INSENSITIVE_ORDER);
+        String mimeType = HttpField.valueParameters(contentType, parameters);
+        if (!mimeType.regionMatches(true, 0, "multipart/", 0, "multipart/".length()))
+            return null;
+        String boundary = parameters.get("boundary");
+        return boundary == null || boundary.isEmpty() ? null : boundary;
+    }
+
+    private static int indexOf(ByteBuffer buffer, byte[] bytes)
+    {
+        int last = buffer.limit() - bytes.length;
+        for (int i = buffer.position(); i <= last; ++i)
+        {
+            int j = 0;
+            while (j < bytes.length && buffer.get(i + j) == bytes[j])
+            {
+                ++j;
+            }
+            if (j == bytes.length)
+                return i;
+        }
+        return -1;
+    }
+
+    /**
+     * <p>Listener for the parts of a multipart response.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Callback method invoked when the headers of a part have been parsed.</p>
+         *
+         * @param part the part
+         */
+        public default void onPart(Part part)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a chunk of the content of a part has been parsed.</p>
+         * <p>The {@code content} buffer is only valid until the {@code callback} is completed,
+         * so it must be copied if it is retained.</p>
+         *
+         * @param part the part
+         * @param content the chunk of content
+         * @param callback the callback to complete when the content has been consumed
+         */
+        public default void onPartContent(Part part, ByteBuffer content, Callback callback)
+        {
+            callback.succeeded();
+        }
+
+        /**
+         * <p>Callback method invoked when the whole content of a part has been parsed.</p>
+         *
+         * @param part the part
+         */
+        public default void onPartEnd(Part part)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the whole multipart response has been parsed.</p>
+         *
+         * @param response the response
+         */
+        public default void onSuccess(Response response)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the response fails, or when its content is not valid multipart.</p>
+         *
+         * @param response the response
+         * @param failure the failure
+         */
+        public default void onFailure(Response response, Throwable failure)
+        {
+        }
+    }
+
+    /**
+     * <p>A part of a multipart response.</p>
+     */
+    public static class Part
+    {
+        private final Part parent;
+        private final HttpFields headers;
+        private final String name;
+        private final String fileName;
+        private final long rangeFirst;
+        private final long rangeLast;
+        private final long completeLength;
+
+        private Part(Part parent, HttpFields headers)
+        {
+            this.parent = parent;
+            this.headers = headers;
+            String value = headers.get(HttpHeader.CONTENT_DISPOSITION);
+            ContentDisposition disposition = value == null ? null : ContentDisposition.parse(value, null);
+            this.name = disposition == null ? null : disposition.getName();
+            this.fileName = disposition == null ? null : disposition.getFileName();
+
+            long first = -1;
+            long last = -1;
+            long length = -1;
+            // Content-Range: bytes <first>-<last>/<length>
+            String contentRange = headers.get(HttpHeader.CONTENT_RANGE);
+            if (contentRange != null && contentRange.regionMatches(true, 0, "bytes ", 0, "bytes ".length()))
+            {
+                String range = contentRange.substring("bytes ".length()).trim();
+                int dash = range.indexOf('-');
+                int slash = range.indexOf('/');
+                try
+                {
+                    if (dash > 0 && slash > dash)
+                    {
+                        first = Long.parseLong(range.substring(0, dash).trim());
+                        last = Long.parseLong(range.substring(dash + 1, slash).trim());
+                    }
+                    String complete = slash < 0 ? "*" : range.substring(slash + 1).trim();
+                    if (!"*".equals(complete))
+                        length = Long.parseLong(complete);
+                }
+                catch (NumberFormatException x)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Invalid Content-Range {}", contentRange, x);
+                    first = last = length = -1;
+                }
+            }
+            this.rangeFirst = first;
+            this.rangeLast = last;
+            this.completeLength = length;
+        }
+
+        /**
+         * @return the enclosing part, if this part is nested in a multipart part, or null
+         */
+        public Part getParent()
+        {
+            return parent;
+        }
+
+        /**
+         * @return the headers of this part
+         */
+        public HttpFields getHeaders()
+        {
+            return headers;
+        }
+
+        /**
+         * @return the value of the {@code Content-Type} header, or null
+         */
+        public String getContentType()
+        {
+            return headers.get(HttpHeader.CONTENT_TYPE);
+        }
+
+        /**
+         * @return the {@code name} parameter of the {@code Content-Disposition} header, or null
+         */
+        public String getName()
+        {
+            return name;
+        }
+
+        /**
+         * @return the {@code filename} parameter of the {@code Content-Disposition} header, or null
+         */
+        public String getFileName()
+        {
+            return fileName;
+        }
+
+        /**
+         * @return the first byte position of the {@code Content-Range} header, or -1
+         */
+        public long getRangeFirst()
+        {
+            return rangeFirst;
+        }
+
+        /**
+         * @return the last byte position of the {@code Content-Range} header, or -1
+         */
+        public long getRangeLast()
+        {
+            return rangeLast;
+        }
+
+        /**
+         * @return the complete length of the {@code Content-Range} header, or -1 if unknown
+         */
+        public long getCompleteLength()
+        {
+            return completeLength;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[name=%s,type=%s,range=%d-%d/%d]", getClass().getSimpleName(), hashCode(), name, getContentType(), rangeFirst, rangeLast, completeLength);
+        }
+    }
+
+    private enum State
+    {
+        PREAMBLE, DELIMITER, HEADERS, CONTENT, EPILOGUE
+    }
+
+    private class Parser
+    {
+        private final byte[] delimiter;
+        private final Part parent;
+        private State state = State.PREAMBLE;
+        // The first boundary may not be preceded by CRLF, so parse as if it were.
+        private ByteBuffer leftover = ByteBuffer.wrap(CRLF);
+        private HttpFields.Mutable headers;
+        private int headersLength;
+        private Part part;
+        private Parser nested;
+
+        private Parser(String boundary, Part parent)
+        {
+            this.delimiter = ("\r\n--" + boundary).getBytes(StandardCharsets.US_ASCII);
+            this.parent = parent;
+        }
+
+        private boolean isComplete()
+        {
+            return state == State.EPILOGUE;
+        }
+
+        private void parse(ByteBuffer chunk, ChunkCallback callback) throws IOException
+        {
+            ByteBuffer data = chunk;
+            if (leftover.hasRemaining())
+            {
+                data = BufferUtil.allocate(leftover.remaining() + chunk.remaining());
+                BufferUtil.append(data, leftover);
+                BufferUtil.append(data, chunk);
+            }
+            leftover = BufferUtil.EMPTY_BUFFER;
+
+            while (true)
+            {
+                switch (state)
+                {
+                    case PREAMBLE:
+                    case CONTENT:
+                    {
+                        int index = indexOf(data, delimiter);
+                        if (index < 0)
+                        {
+                            // Retain the bytes that may be the beginning of the delimiter.
+                            int end = Math.max(data.position(), data.limit() - (delimiter.length - 1));
+                            content(data, end, callback);
+                            leftover = BufferUtil.copy(data);
+                            return;
+                        }
+                        content(data, index, callback);
+                        data.position(index + delimiter.length);
+                        if (state == State.CONTENT)
+                            endPart();
+                        state = State.DELIMITER;
+                        break;
+                    }
+                    case DELIMITER:
+                    {
+                        int position = data.position();
+                        if (data.remaining() < 2)
+                        {
+                            leftover = BufferUtil.copy(data);
+                            return;
+                        }
+                        if (data.get(position) == '-' && data.get(position + 1) == '-')
+                        {
+                            // Close delimiter, the rest is the epilogue.
+                            state = State.EPILOGUE;
+                            break;
+                        }
+                        int eol = indexOf(data, CRLF);
+                        if (eol < 0)
+                        {
+                            if (data.remaining() > maxHeadersLength)
+                                throw new IOException("Invalid multipart delimiter");
+                            leftover = BufferUtil.copy(data);
+                            return;
+                        }
+                        for (int i = position; i < eol; ++i)
+                        {
+                            byte b = data.get(i);
+                            if (b != ' ' && b != '\t')
+                                throw new IOException("Invalid multipart delimiter");
+                        }
+                        data.position(eol + CRLF.length);
+                        headers = HttpFields.build();
+                        headersLength = 0;
+                        state = State.HEADERS;
+                        break;
+                    }
+                    case HEADERS:
+                    {
+                        int position = data.position();
+                        int eol = indexOf(data, CRLF);
+                        if (eol < 0)
+                        {
+                            if (headersLength + data.remaining() > maxHeadersLength)
+                                throw new IOException("Multipart headers too large");
+                            leftover = BufferUtil.copy(data);
+                            return;
+                        }
+                        headersLength += eol - position + CRLF.length;
+                        if (headersLength > maxHeadersLength)
+                            throw new IOException("Multipart headers too large");
+                        byte[] bytes = new byte[eol - position];
+                        data.get(bytes);
+                        data.position(eol + CRLF.length);
+                        String line = new String(bytes, StandardCharsets.UTF_8);
+                        if (line.isEmpty())
+                        {
+                            startPart();
+                            state = State.CONTENT;
+                        }
+                        else
+                        {
+                            int colon = line.indexOf(':');
+                            if (colon <= 0)
+                                throw new IOException("Invalid multipart header: " + line);
+                            headers.add(line.substring(0, colon).trim(), line.substring(colon + 1).trim());
+                        }
+                        break;
+                    }
+                    case EPILOGUE:
+                    {
+                        data.position(data.limit());
+                        return;
+                    }
+                    default:
+                    {
+                        throw new IllegalStateException("Invalid state " + state);
+                    }
+                }
+            }
+        }
+
+        private void content(ByteBuffer data, int end, ChunkCallback callback) throws IOException
+        {
+            if (state == State.CONTENT && end > data.position())
+            {
+                ByteBuffer content = data.slice();
+                content.limit(end - data.position());
+                if (nested != null)
+                {
+                    nested.parse(content, callback);
+                }
+                else
+                {
+                    callback.retain();
+                    listener.onPartContent(part, content, callback);
+                }
+            }
+            data.position(end);
+        }
+
+        private void startPart()
+        {
+            part = new Part(parent, headers);
+            headers = null;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Parsed part headers {}", part);
+            listener.onPart(part);
+            String boundary = boundary(part.getContentType());
+            if (boundary != null)
+                nested = new Parser(boundary, part);
+        }
+
+        private void endPart() throws IOException
+        {
+            if (nested != null && !nested.isComplete())
+                throw new EOFException("Truncated nested multipart part");
+            nested = null;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Parsed part {}", part);
+            listener.onPartEnd(part);
+            part = null;
+        }
+    }
+
+    /**
+     * <p>The callback of a network content chunk, that demands more content
+     * when all the part content notified from the chunk has been consumed.</p>
+     */
+    private static class ChunkCallback implements Callback
+    {
+        private final AtomicInteger pending = new AtomicInteger(1);
+        private final AtomicBoolean failed = new AtomicBoolean();
+        private final Response response;
+        private final LongConsumer demand;
+        private final Callback callback;
+
+        private ChunkCallback(Response response, LongConsumer demand, Callback callback)
+        {
+            this.response = response;
+            this.demand = demand;
+            this.callback = callback;
+        }
+
+        private void retain()
+        {
+            pending.incrementAndGet();
+        }
+
+        @Override
+        public void succeeded()
+        {
+            if (pending.decrementAndGet() == 0 && !failed.get())
+            {
+                callback.succeeded();
+                demand.accept(1);
+            }
+        }
+
+        @Override
+        public void failed(Throwable x)
+        {
+            if (failed.compareAndSet(false, true))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Failed multipart content", x);
+                callback.failed(x);
+                response.abort(x);
+            }
+        }
+    }
+}
@@ -0,0 +1,539 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.net.URI;
+import java.time.Duration;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpResponseException;
+import org.eclipse.jetty.client.api.AuthenticationStore;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.Attributes;
+import org.eclipse.jetty.util.Fields;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Implementation of the OAuth 2.0 "Bearer" authentication defined in RFC 6750,
+ * where access tokens are obtained from a {@link TokenEndpoint} using the
+ * "client_credentials" or "refresh_token" grants defined in RFC 6749.</p>
+ * <p>Access tokens are cached per realm, and are automatically renewed when they
+ * are about to expire, within the {@link #setRefreshMargin(Duration) refresh margin};
+ * if a token has an associated refresh token, the "refresh_token" grant is tried
+ * first, falling back to the "client_credentials" grant.
+ * Access tokens rejected by the server are discarded and a new token is obtained.</p>
+ * <p>Token requests are blocking; when using {@link HttpTokenEndpoint} with the same
+ * {@link HttpClient} that sends the requests to the protected resources, make sure
+ * that the {@link HttpClient} executor has enough threads.</p>
+ * <p>Applications should create objects of this class and add them to the
+ * {@link AuthenticationStore} retrieved from the {@link HttpClient}
+ * via {@link HttpClient#getAuthenticationStore()}.</p>
+ */
+public class OAuth2Authentication extends AbstractAuthentication
+{
+    private static final Logger LOG = LoggerFactory.getLogger(OAuth2Authentication.class);
+    private static final String BEARER = "Bearer";
+
+    private final AutoLock lock = new AutoLock();
+    private final Map<String, Token> tokens = new HashMap<>();
+    private final TokenEndpoint tokenEndpoint;
+    private String scope;
+    private String refreshToken;
+    private Duration refreshMargin = Duration.ofSeconds(30);
+
+    /**
+     * @param uri the URI to match for the authentication
+     * @param realm the realm to match for the authentication
+     * @param tokenEndpoint the endpoint that issues the access tokens
+     */
+    public OAuth2Authentication(URI uri, String realm, TokenEndpoint tokenEndpoint)
+    {
+        super(uri, realm);
+        this.tokenEndpoint = Objects.requireNonNull(tokenEndpoint);
+    }
+
+    @Override
+    public String getType()
+    {
+        return BEARER;
+    }
+
+    /**
+     * @return the endpoint that issues the access tokens
+     */
+    public TokenEndpoint getTokenEndpoint()
+    {
+        return tokenEndpoint;
+    }
+
+    /**
+     * @return the scope of the access tokens to request, or null for the default scope
+     */
+    public String getScope()
+    {
+        return scope;
+    }
+
+    /**
+     * @param scope the space separated scope of the access tokens to request, or null for the default scope
+     */
+    public void setScope(String scope)
+    {
+        this.scope = scope;
+    }
+
+    /**
+     * @return the initial refresh token
+     */
+    public String getRefreshToken()
+    {
+        return refreshToken;
+    }
+
+    /**
+     * <p>Sets a refresh token obtained by other means, for example via an
+     * authorization code grant, used to request the first access token
+     * of every realm.</p>
+     *
+     * @param refreshToken the initial refresh token
+     */
+    public void setRefreshToken(String refreshToken)
+    {
+        this.refreshToken = refreshToken;
+    }
+
+    /**
+     * @return the time before the expiration of an access token when the token is renewed
+     */
+    public Duration getRefreshMargin()
+    {
+        return refreshMargin;
+    }
+
+    /**
+     * @param refreshMargin the time before the expiration of an access token when the token is renewed
+     */
+    public void setRefreshMargin(Duration refreshMargin)
+    {
+        if (refreshMargin.isNegative())
+            throw new IllegalArgumentException("Invalid refresh margin " + refreshMargin);
+        this.refreshMargin = refreshMargin;
+    }
+
+    /**
+     * <p>Returns a valid access token for the given realm,
+     * requesting a new one if necessary.</p>
+     *
+     * @param realm the realm, or null if the server did not specify a realm
+     * @return a valid access token
+     * @throws Exception if the access token could not be obtained
+     */
+    public Token getToken(String realm) throws Exception
+    {
+        return getToken(realm, null);
+    }
+
+    /**
+     * <p>Discards all the cached access tokens.</p>
+     */
+    public void clearTokens()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            tokens.clear();
+        }
+    }
+
+    @Override
+    public Result authenticate(Request request, ContentResponse response, HeaderInfo headerInfo, Attributes context)
+    {
+        // The server rejected the access token, if any, sent with the request.
+        String rejected = null;
+        String value = request.getHeaders().get(headerInfo.getHeader());
+        if (value != null && value.regionMatches(true, 0, BEARER + " ", 0, BEARER.length() + 1))
+            rejected = value.substring(BEARER.length() + 1).trim();
+
+        String realm = headerInfo.getRealm();
+        try
+        {
+            getToken(realm, rejected);
+        }
+        catch (RuntimeException x)
+        {
+            throw x;
+        }
+        catch (Exception x)
+        {
+            throw new RuntimeException(x);
+        }
+        return new OAuth2Result(getURI(), headerInfo.getHeader(), realm);
+    }
+
+    private Token getToken(String realm, String rejected) throws Exception
+    {
+        String key = realm == null ? "" : realm;
+        try (AutoLock l = lock.lock())
+        {
+            Token token = tokens.get(key);
+            if (token != null)
+            {
+                if (token.getAccessToken().equals(rejected))
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Access token rejected for realm {}", realm);
+                }
+                else if (!token.isExpired(refreshMargin))
+                {
+                    return token;
+                }
+            }
+
+            String refresh = token == null ? refreshToken : token.getRefreshToken();
+            Token newToken = null;
+            if (refresh != null)
+            {
+                try
+                {
+                    newToken = tokenEndpoint.requestToken(newRefreshTokenGrant(refresh));
+                }
+                catch (Exception x)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not refresh access token for realm {}", realm, x);
+                }
+            }
+            if (newToken == null)
+                newToken = tokenEndpoint.requestToken(newClientCredentialsGrant());
+            // The refresh token may be reused if a new one is not issued.
+            else if (newToken.getRefreshToken() == null)
+                newToken = new Token(newToken.getAccessToken(), refresh, newToken.getExpiresIn(), newToken.getScope());
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Obtained {} for realm {}", newToken, realm);
+            tokens.put(key, newToken);
+            return newToken;
+        }
+    }
+
+    private Fields newClientCredentialsGrant()
+    {
+        Fields fields = new Fields();
+        fields.add("grant_type", "client_credentials");
+        if (scope != null)
+            fields.add("scope", scope);
+        return fields;
+    }
+
+    private Fields newRefreshTokenGrant(String refreshToken)
+    {
+        Fields fields = new Fields();
+        fields.add("grant_type", "refresh_token");
+        fields.add("refresh_token", refreshToken);
+        if (scope != null)
+            fields.add("scope", scope);
+        return fields;
+    }
+
+    /**
+     * <p>An OAuth 2.0 access token, with its optional refresh token and expiration.</p>
+     */
+    public static class Token
+    {
+        private final String accessToken;
+        private final String refreshToken;
+        private final Duration expiresIn;
+        private final String scope;
+        private final long creationNanoTime = NanoTime.now();
+
+        /**
+         * @param accessToken the access token
+         * @param refreshToken the refresh token, or null
+         * @param expiresIn the lifetime of the access token, or null if the access token does not expire
+         * @param scope the scope of the access token, or null
+         */
+        public Token(String accessToken, String refreshToken, Duration expiresIn, String scope)
+        {
+            this.accessToken = Objects.requireNonNull(accessToken);
+            this.refreshToken = refreshToken;
+            this.expiresIn = expiresIn;
+            this.scope = scope;
+        }
+
+        public String getAccessToken()
+        {
+            return accessToken;
+        }
+
+        public String getRefreshToken()
+        {
+            return refreshToken;
+        }
+
+        public Duration getExpiresIn()
+        {
+            return expiresIn;
+        }
+
+        public String getScope()
+        {
+            return scope;
+        }
+
+        /**
+         * @param margin the time before the actual expiration when the token is considered expired
+         * @return whether this token is expired, or expires within the given margin
+         */
+        public boolean isExpired(Duration margin)
+        {
+            if (expiresIn == null)
+                return false;
+            return NanoTime.since(creationNanoTime) + margin.toNanos() >= expiresIn.toNanos();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[expiresIn=%s,refreshable=%b,scope=%s]", getClass().getSimpleName(), hashCode(), expiresIn, refreshToken != null, scope);
+        }
+    }
+
+    /**
+     * <p>The endpoint that issues the access tokens.</p>
+     */
+    @FunctionalInterface
+    public interface TokenEndpoint
+    {
+        /**
+         * <p>Requests an access token.</p>
+         * <p>This method is called with a lock held, so that concurrent
+         * requests do not request multiple access tokens.</p>
+         *
+         * @param parameters the token request parameters, such as {@code grant_type}, {@code scope}
+         * and {@code refresh_token}
+         * @return the access token
+         * @throws Exception if the access token could not be obtained
+         */
+        Token requestToken(Fields parameters) throws Exception;
+    }
+
+    /**
+     * <p>A {@link TokenEndpoint} that requests access tokens to an authorization
+     * server via HTTP, as defined in RFC 6749.</p>
+     * <p>The client authenticates with the authorization server using its client id
+     * and client secret, either via the {@code Authorization} header (the default)
+     * or via request parameters.</p>
+     * <p>This class requires the {@code jetty-util-ajax} module to parse the responses.</p>
+     */
+    public static class HttpTokenEndpoint implements TokenEndpoint
+    {
+        private final HttpClient httpClient;
+        private final URI tokenURI;
+        private final String clientId;
+        private final String clientSecret;
+        private boolean clientSecretPost;
+        private long timeout = 10000;
+
+        /**
+         * @param httpClient the HttpClient used to request access tokens
+         * @param tokenURI the URI of the token endpoint of the authorization server
+         * @param clientId the client id
+         * @param clientSecret the client secret
+         */
+        public HttpTokenEndpoint(HttpClient httpClient, URI tokenURI, String clientId, String clientSecret)
+        {
+            this.httpClient = Objects.requireNonNull(httpClient);
+            this.tokenURI = Objects.requireNonNull(tokenURI);
+            this.clientId = Objects.requireNonNull(clientId);
+            this.clientSecret = clientSecret;
+        }
+
+        public URI getTokenURI()
+        {
+            return tokenURI;
+        }
+
+        public String getClientId()
+        {
+            return clientId;
+        }
+
+        /**
+         * @return whether the client credentials are sent as request parameters
+         */
+        public boolean isClientSecretPost()
+        {
+            return clientSecretPost;
+        }
+
+        /**
+         * @param clientSecretPost whether the client credentials are sent as request
+         * parameters, rather than via the {@code Authorization} header
+         */
+        public void setClientSecretPost(boolean clientSecretPost)
+        {
+            this.clientSecretPost = clientSecretPost;
+        }
+
+        /**
+         * @return the timeout in milliseconds of the token requests
+         */
+        public long getTimeout()
+        {
+            return timeout;
+        }
+
+        /**
+         * @param timeout the timeout in milliseconds of the token requests
+         */
+        public void setTimeout(long timeout)
+        {
+            this.timeout = timeout;
+        }
+
+        @Override
+        public Token requestToken(Fields parameters) throws Exception
+        {
+            Fields fields = new Fields();
+            parameters.forEach(fields::put);
+            Request request = httpClient.POST(tokenURI)
+                .headers(headers -> headers.put(HttpHeader.ACCEPT, "application/json"))
+                .timeout(timeout, TimeUnit.MILLISECONDS);
+            if (clientSecretPost)
+            {
+                fields.put("client_id", clientId);
+                if (clientSecret != null)
+                    fields.put("client_secret", clientSecret);
+            }
+            else
+            {
+                new BasicAuthentication.BasicResult(tokenURI, clientId, clientSecret == null ? "" : clientSecret).apply(request);
+            }
+
+            ContentResponse response = request.body(new FormRequestContent(fields)).send();
+            String body = response.getContentAsString();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Token response {} {}", response.getStatus(), body);
+
+            Map<?, ?> json = parse(body);
+            if (response.getStatus() != HttpStatus.OK_200)
+            {
+                Object error = json == null ? null : json.get("error");
+                Object description = json == null ? null : json.get("error_description");
+                throw new HttpResponseException(String.format("Token request failed: %d %s%s", response.getStatus(),
+                    error == null ? response.getReason() : error, description == null ? "" : " - " + description), response);
+            }
+            if (json == null)
+                throw new HttpResponseException("Malformed token response", response);
+
+            Object accessToken = json.get("access_token");
+            if (!(accessToken instanceof String))
+                throw new HttpResponseException("Missing access_token in token response", response);
+            Object tokenType = json.get("token_type");
+            if (tokenType != null && !BEARER.equalsIgnoreCase(tokenType.toString()))
+                throw new HttpResponseException("Unsupported token_type " + tokenType, response);
+            Object refreshToken = json.get("refresh_token");
+            Object expiresIn = json.get("expires_in");
+            Duration lifetime = null;
+            if (expiresIn instanceof Number)
+                lifetime = Duration.ofSeconds(((Number)expiresIn).longValue());
+            else if (expiresIn instanceof String)
+                lifetime = Duration.ofSeconds(Long.parseLong((String)expiresIn));
+            Object scope = json.get("scope");
+            return new Token((String)accessToken, refreshToken == null ? null : refreshToken.toString(), lifetime, scope == null ? null : scope.toString());
+        }
+
+        private static Map<?, ?> parse(String body)
+        {
+            try
+            {
+                Object json = new JSON().fromJSON(body);
+                return json instanceof Map ? (Map<?, ?>)json : null;
+            }
+            catch (IllegalStateException x)
+            {
+                return null;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), tokenURI);
+        }
+    }
+
+    private class OAuth2Result implements Result
+    {
+        private final URI uri;
+        private final HttpHeader header;
+        private final String realm;
+
+        private OAuth2Result(URI uri, HttpHeader header, String realm)
+        {
+            this.uri = uri;
+            this.header = header;
+            this.realm = realm;
+        }
+
+        @Override
+        public URI getURI()
+        {
+            return uri;
+        }
+
+        @Override
+        public void apply(Request request)
+        {
+            try
+            {
+                // Renews the access token if it is about to expire.
+                Token token = getToken(realm);
+                request.headers(headers -> headers.put(header, BEARER + " " + token.getAccessToken()));
+            }
+            catch (Exception x)
+            {
+                // Send the request without access token, so that the
+                // server challenges again and the failure is reported.
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not obtain access token for realm {}", realm, x);
+                clearToken(realm);
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("Bearer authentication result for %s", getURI());
+        }
+    }
+
+    private void clearToken(String realm)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            tokens.remove(realm == null ? "" : realm);
+        }
+    }
+}
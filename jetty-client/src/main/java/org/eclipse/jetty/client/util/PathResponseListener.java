@@ -0,0 +1,209 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.util.concurrent.CompletableFuture;
+
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.ContentDisposition;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.URIUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Implementation of {@link Response.Listener} that spools the response content to a file.</p>
+ * <p>The response content buffers are written directly to a {@link FileChannel}, without
+ * being copied or accumulated in memory, so that very large downloads can be stored
+ * without GC pressure.</p>
+ * <p>If the path is an existing directory, the file is created in that directory,
+ * with the name taken from the {@code Content-Disposition} response header, or
+ * from the last segment of the request URI path if the header is absent.
+ * Only the last segment of the file name sent by the server is used, so that
+ * the file cannot be created outside the directory.</p>
+ * <p>This listener is a {@link CompletableFuture} that is completed with the file
+ * path when the response is successfully received, or completed exceptionally
+ * (and the file deleted) if the response fails.</p>
+ * <p>Instances of this class are not reusable, so one must be allocated for each request.</p>
+ */
+public class PathResponseListener extends CompletableFuture<Path> implements Response.Listener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PathResponseListener.class);
+
+    private final Path path;
+    private final boolean overwrite;
+    private volatile Path file;
+    private FileChannel channel;
+    private long written;
+
+    public PathResponseListener(Path path)
+    {
+        this(path, true);
+    }
+
+    /**
+     * @param path the file to write the response content to, or the directory to create the file in
+     * @param overwrite whether an existing file is overwritten, otherwise the response fails
+     */
+    public PathResponseListener(Path path, boolean overwrite)
+    {
+        this.path = path;
+        this.overwrite = overwrite;
+        this.file = path;
+    }
+
+    /**
+     * @return the file the response content is written to; when this listener
+     * is created with a directory, the file is known only after the response
+     * headers have been received
+     */
+    public Path getPath()
+    {
+        return file;
+    }
+
+    /**
+     * @return the number of content bytes written to the file so far
+     */
+    public long getBytesWritten()
+    {
+        return written;
+    }
+
+    @Override
+    public void onHeaders(Response response)
+    {
+        try
+        {
+            if (Files.isDirectory(path))
+                file = path.resolve(fileName(response));
+            channel = overwrite
+                ? FileChannel.open(file, StandardOpenOption.CREATE, StandardOpenOption.WRITE, StandardOpenOption.TRUNCATE_EXISTING)
+                : FileChannel.open(file, StandardOpenOption.CREATE_NEW, StandardOpenOption.WRITE);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Opened file {}", file);
+        }
+        catch (Throwable x)
+        {
+            response.abort(x);
+        }
+    }
+
+    @Override
+    public void onContent(Response response, ByteBuffer content, Callback callback)
+    {
+        try
+        {
+            while (content.hasRemaining())
+            {
+                written += channel.write(content);
+            }
+            callback.succeeded();
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+            response.abort(x);
+        }
+    }
+
+    @Override
+    public void onComplete(Result result)
+    {
+        try
+        {
+            if (channel != null)
+                channel.force(false);
+        }
+        catch (IOException x)
+        {
+            if (!result.isFailed())
+                result = new Result(result, x);
+        }
+        IO.close(channel);
+
+        if (result.isFailed())
+        {
+            if (channel != null)
+                delete();
+            completeExceptionally(result.getFailure());
+        }
+        else
+        {
+            complete(file);
+        }
+    }
+
+    private static String fileName(Response response) throws IOException
+    {
+        String fileName = null;
+        String value = response.getHeaders().get(HttpHeader.CONTENT_DISPOSITION);
+        if (value != null)
+        {
+            ContentDisposition disposition = ContentDisposition.parse(value, null);
+            if (disposition != null)
+                fileName = sanitize(disposition.getFileName());
+        }
+        if (fileName == null)
+        {
+            String uriPath = response.getRequest().getURI().getRawPath();
+            if (uriPath != null)
+                fileName = sanitize(URIUtil.decodePath(uriPath));
+        }
+        if (fileName == null)
+            throw new IOException("No file name for " + response.getRequest().getURI());
+        return fileName;
+    }
+
+    private static String sanitize(String fileName)
+    {
+        if (fileName == null)
+            return null;
+        // Keep only the last segment of both Unix and Windows paths.
+        int slash = Math.max(fileName.lastIndexOf('/'), fileName.lastIndexOf('\\'));
+        fileName = fileName.substring(slash + 1).trim();
+        StringBuilder builder = new StringBuilder(fileName.length());
+        for (int i = 0; i < fileName.length(); ++i)
+        {
+            char c = fileName.charAt(i);
+            builder.append(Character.isISOControl(c) || c == ':' ? '_' : c);
+        }
+        fileName = builder.toString();
+        if (fileName.isEmpty() || ".".equals(fileName) || "..".equals(fileName))
+            return null;
+        return fileName;
+    }
+
+    private void delete()
+    {
+        try
+        {
+            Files.deleteIfExists(file);
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not delete {}", file, x);
+        }
+    }
+}
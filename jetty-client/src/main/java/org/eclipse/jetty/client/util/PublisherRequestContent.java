@@ -0,0 +1,176 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.nio.ByteBuffer;
+import java.util.Objects;
+import java.util.concurrent.Flow;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.util.Callback;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Request.Content} that produces the content items published by a {@link Flow.Publisher}.</p>
+ * <p>The publisher is subscribed when the request content is subscribed, that is when the
+ * request is sent, and only one item at a time is requested from the publisher: the next
+ * item is requested only after the previous one has been written to the network, so that
+ * a fast publisher cannot overwhelm a slow connection.</p>
+ * <p>When the publisher signals {@link Flow.Subscriber#onComplete() onComplete()} the request
+ * content is terminated, while {@link Flow.Subscriber#onError(Throwable) onError(Throwable)}
+ * fails the request.
+ * Conversely, if the request is aborted or fails, the publisher subscription is cancelled.</p>
+ * <p>The {@link ByteBuffer}s published must not be modified by the publisher after they
+ * have been published.</p>
+ *
+ * @see PublisherResponseListener
+ */
+public class PublisherRequestContent implements Request.Content
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PublisherRequestContent.class);
+
+    private final AtomicBoolean subscribed = new AtomicBoolean();
+    private final Flow.Publisher<ByteBuffer> publisher;
+    private final AsyncRequestContent content;
+    private final Bridge bridge = new Bridge();
+
+    public PublisherRequestContent(Flow.Publisher<ByteBuffer> publisher)
+    {
+        this("application/octet-stream", publisher);
+    }
+
+    public PublisherRequestContent(String contentType, Flow.Publisher<ByteBuffer> publisher)
+    {
+        this.publisher = Objects.requireNonNull(publisher);
+        this.content = new AsyncRequestContent(contentType);
+    }
+
+    @Override
+    public String getContentType()
+    {
+        return content.getContentType();
+    }
+
+    @Override
+    public Subscription subscribe(Consumer consumer, boolean emitInitialContent)
+    {
+        if (!subscribed.compareAndSet(false, true))
+            throw new IllegalStateException("Multiple subscriptions not supported on " + this);
+        Subscription subscription = content.subscribe(consumer, emitInitialContent);
+        publisher.subscribe(bridge);
+        return new Subscription()
+        {
+            @Override
+            public void demand()
+            {
+                subscription.demand();
+            }
+
+            @Override
+            public void fail(Throwable failure)
+            {
+                PublisherRequestContent.this.fail(failure);
+            }
+        };
+    }
+
+    @Override
+    public void fail(Throwable failure)
+    {
+        content.fail(failure);
+        bridge.cancel();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), publisher);
+    }
+
+    private class Bridge implements Flow.Subscriber<ByteBuffer>
+    {
+        private final AtomicReference<Flow.Subscription> subscription = new AtomicReference<>();
+
+        @Override
+        public void onSubscribe(Flow.Subscription subscription)
+        {
+            // A cancel() that happened before the subscription cancels it immediately.
+            if (!this.subscription.compareAndSet(null, subscription))
+            {
+                subscription.cancel();
+                return;
+            }
+            subscription.request(1);
+        }
+
+        @Override
+        public void onNext(ByteBuffer buffer)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Published {} for {}", buffer, PublisherRequestContent.this);
+            content.offer(buffer, Callback.from(this::request, this::failed));
+        }
+
+        private void request()
+        {
+            Flow.Subscription subscription = this.subscription.get();
+            if (subscription != null)
+                subscription.request(1);
+        }
+
+        private void failed(Throwable failure)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failed to write published content for {}", PublisherRequestContent.this, failure);
+            cancel();
+        }
+
+        @Override
+        public void onError(Throwable failure)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Publisher failed for {}", PublisherRequestContent.this, failure);
+            content.fail(failure);
+        }
+
+        @Override
+        public void onComplete()
+        {
+            content.close();
+        }
+
+        private void cancel()
+        {
+            Flow.Subscription subscription = this.subscription.getAndSet(new CancelledSubscription());
+            if (subscription != null)
+                subscription.cancel();
+        }
+    }
+
+    private static class CancelledSubscription implements Flow.Subscription
+    {
+        @Override
+        public void request(long n)
+        {
+        }
+
+        @Override
+        public void cancel()
+        {
+        }
+    }
+}
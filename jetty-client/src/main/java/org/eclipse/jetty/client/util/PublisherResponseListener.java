@@ -0,0 +1,261 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.nio.ByteBuffer;
+import java.util.Objects;
+import java.util.concurrent.CancellationException;
+import java.util.concurrent.Flow;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.MathUtils;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Response.Listener} that is also a {@link Flow.Publisher} of the response content.</p>
+ * <p>Response content is demanded from the network only when the {@link Flow.Subscriber}
+ * has outstanding demand, so that a slow subscriber applies backpressure all the way to the
+ * server, and the subscriber receives one item per response content buffer.
+ * The items are copies of the network buffers, so the subscriber may retain them.</p>
+ * <p>The subscriber is notified of {@link Flow.Subscriber#onComplete() onComplete()} when the
+ * response completes successfully, and of {@link Flow.Subscriber#onError(Throwable) onError(Throwable)}
+ * when the request or the response fail.
+ * Cancelling the {@link Flow.Subscription} aborts the response.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * PublisherResponseListener listener = new PublisherResponseListener();
+ * httpClient.newRequest(uri).send(listener);
+ * listener.subscribe(subscriber);
+ * }</pre>
+ * <p>Only one subscriber is supported; the subscriber may subscribe before or after the
+ * request is sent.</p>
+ *
+ * @see PublisherRequestContent
+ */
+public class PublisherResponseListener extends Response.Listener.Adapter implements Flow.Publisher<ByteBuffer>
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PublisherResponseListener.class);
+
+    private final AutoLock lock = new AutoLock();
+    private Flow.Subscriber<? super ByteBuffer> subscriber;
+    private Response response;
+    private LongConsumer stalled;
+    private long demand;
+    private boolean notifying;
+    private boolean cancelled;
+    private boolean terminated;
+    private Result result;
+
+    @Override
+    public void subscribe(Flow.Subscriber<? super ByteBuffer> subscriber)
+    {
+        Objects.requireNonNull(subscriber);
+        boolean rejected;
+        try (AutoLock ignored = lock.lock())
+        {
+            rejected = this.subscriber != null;
+            if (!rejected)
+                this.subscriber = subscriber;
+        }
+        if (rejected)
+        {
+            subscriber.onSubscribe(new Flow.Subscription()
+            {
+                @Override
+                public void request(long n)
+                {
+                }
+
+                @Override
+                public void cancel()
+                {
+                }
+            });
+            subscriber.onError(new IllegalStateException("Multiple subscriptions not supported on " + this));
+            return;
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("Subscribed {} to {}", subscriber, this);
+        subscriber.onSubscribe(new Subscription());
+        // The response may have already completed.
+        terminate();
+    }
+
+    @Override
+    public void onBegin(Response response)
+    {
+        boolean abort;
+        try (AutoLock ignored = lock.lock())
+        {
+            this.response = response;
+            abort = cancelled;
+        }
+        if (abort)
+            response.abort(new CancellationException());
+    }
+
+    @Override
+    public void onBeforeContent(Response response, LongConsumer demand)
+    {
+        demand(demand);
+    }
+
+    @Override
+    public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+    {
+        ByteBuffer item = BufferUtil.copy(content);
+        callback.succeeded();
+
+        Flow.Subscriber<? super ByteBuffer> subscriber;
+        try (AutoLock ignored = lock.lock())
+        {
+            if (cancelled)
+                return;
+            subscriber = this.subscriber;
+            if (this.demand < Long.MAX_VALUE)
+                --this.demand;
+            notifying = true;
+        }
+
+        try
+        {
+            subscriber.onNext(item);
+        }
+        finally
+        {
+            try (AutoLock ignored = lock.lock())
+            {
+                notifying = false;
+            }
+        }
+
+        demand(demand);
+        // The response may have completed while notifying the subscriber.
+        terminate();
+    }
+
+    @Override
+    public void onComplete(Result result)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            this.result = result;
+        }
+        terminate();
+    }
+
+    private void demand(LongConsumer demand)
+    {
+        boolean proceed;
+        try (AutoLock ignored = lock.lock())
+        {
+            proceed = this.demand > 0 && !cancelled;
+            if (!proceed)
+                stalled = demand;
+        }
+        if (proceed)
+            demand.accept(1);
+    }
+
+    private void terminate()
+    {
+        Flow.Subscriber<? super ByteBuffer> subscriber;
+        Throwable failure;
+        try (AutoLock ignored = lock.lock())
+        {
+            if (terminated || notifying || result == null || this.subscriber == null)
+                return;
+            terminated = true;
+            if (cancelled)
+                return;
+            subscriber = this.subscriber;
+            failure = result.getFailure();
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("Terminating {} with {}", this, failure == null ? "success" : failure);
+        if (failure == null)
+            subscriber.onComplete();
+        else
+            subscriber.onError(failure);
+    }
+
+    @Override
+    public String toString()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return String.format("%s@%x[demand=%d,cancelled=%b,terminated=%b]", getClass().getSimpleName(), hashCode(), demand, cancelled, terminated);
+        }
+    }
+
+    private class Subscription implements Flow.Subscription
+    {
+        @Override
+        public void request(long n)
+        {
+            if (n <= 0)
+            {
+                Flow.Subscriber<? super ByteBuffer> subscriber;
+                try (AutoLock ignored = lock.lock())
+                {
+                    if (cancelled || terminated)
+                        return;
+                    terminated = true;
+                    subscriber = PublisherResponseListener.this.subscriber;
+                }
+                cancel();
+                subscriber.onError(new IllegalArgumentException("Invalid demand " + n));
+                return;
+            }
+
+            LongConsumer demand;
+            try (AutoLock ignored = lock.lock())
+            {
+                if (cancelled)
+                    return;
+                PublisherResponseListener.this.demand = MathUtils.cappedAdd(PublisherResponseListener.this.demand, n);
+                demand = stalled;
+                stalled = null;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Demand {} for {}", n, PublisherResponseListener.this);
+            if (demand != null)
+                demand.accept(1);
+        }
+
+        @Override
+        public void cancel()
+        {
+            Response response;
+            try (AutoLock ignored = lock.lock())
+            {
+                if (cancelled)
+                    return;
+                cancelled = true;
+                stalled = null;
+                response = PublisherResponseListener.this.response;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Cancelled {}", PublisherResponseListener.this);
+            if (response != null)
+                response.abort(new CancellationException());
+        }
+    }
+}
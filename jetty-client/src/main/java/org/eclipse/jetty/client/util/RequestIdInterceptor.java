@@ -0,0 +1,89 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.util.Objects;
+import java.util.function.Supplier;
+
+import org.eclipse.jetty.client.Interceptor;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.slf4j.MDC;
+
+/**
+ * <p>An {@link Interceptor} that forwards the ID of the current request
+ * to downstream services, so that the calls between services can be
+ * correlated in the logs.</p>
+ * <p>By default, the current request ID is taken from the SLF4J {@link MDC}
+ * key {@value #DEFAULT_MDC_KEY}, where the server-side
+ * {@code org.eclipse.jetty.server.handler.RequestIdHandler} puts it while
+ * handling a request, and it is sent in the {@value #DEFAULT_HEADER} header,
+ * unless the request already has that header.</p>
+ * <pre>{@code
+ * httpClient.addInterceptor(new RequestIdInterceptor());
+ * }</pre>
+ * <p>The current request ID is captured when the request is sent, so requests
+ * must be sent from the thread that handles the server-side request, or the
+ * request ID must be provided by a custom {@link Supplier}.</p>
+ */
+public class RequestIdInterceptor implements Interceptor
+{
+    public static final String DEFAULT_HEADER = "X-Request-Id";
+    public static final String DEFAULT_MDC_KEY = "requestId";
+
+    private final String header;
+    private final Supplier<String> requestId;
+
+    /**
+     * <p>Creates an interceptor that forwards the {@value #DEFAULT_MDC_KEY} MDC value
+     * in the {@value #DEFAULT_HEADER} header.</p>
+     */
+    public RequestIdInterceptor()
+    {
+        this(DEFAULT_HEADER, () -> MDC.get(DEFAULT_MDC_KEY));
+    }
+
+    /**
+     * @param header the name of the header carrying the request ID
+     * @param requestId the supplier of the current request ID, that may return null if there is no current request ID
+     */
+    public RequestIdInterceptor(String header, Supplier<String> requestId)
+    {
+        this.header = Objects.requireNonNull(header);
+        this.requestId = Objects.requireNonNull(requestId);
+    }
+
+    /**
+     * @return the name of the header carrying the request ID
+     */
+    public String getHeader()
+    {
+        return header;
+    }
+
+    @Override
+    public void intercept(Request request, Response.Listener listener, Chain chain)
+    {
+        String id = requestId.get();
+        if (id != null && !request.getHeaders().contains(header))
+            request.headers(headers -> headers.put(header, id));
+        chain.proceed(request, listener);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), header);
+    }
+}
@@ -0,0 +1,303 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.util.EventListener;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.util.NanoTime;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Tracks the progress of the upload of the request content and of the
+ * download of the response content of a request.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * Request request = httpClient.newRequest(uri).file(path);
+ * TransferProgress.track(request, new TransferProgress.Listener()
+ * {
+ *     @Override
+ *     public void onUploadProgress(Request request, TransferProgress.Transfer upload)
+ *     {
+ *         System.err.printf("sent %d/%d bytes at %d B/s%n", upload.getBytes(), upload.getTotalBytes(), upload.getThroughput());
+ *     }
+ * });
+ * ContentResponse response = request.send();
+ * }</pre>
+ * <p>If the request is retried, the upload progress restarts with every attempt,
+ * while the download progress only reports the response of the last attempt.</p>
+ */
+public class TransferProgress
+{
+    private static final Logger LOG = LoggerFactory.getLogger(TransferProgress.class);
+
+    private final Transfer upload = new Transfer();
+    private final Transfer download = new Transfer();
+    private final Listener listener;
+
+    private TransferProgress(Listener listener)
+    {
+        this.listener = Objects.requireNonNull(listener);
+    }
+
+    /**
+     * <p>Tracks the progress of the given request, notifying the given listener.</p>
+     *
+     * @param request the request to track
+     * @param listener the listener to notify of the progress
+     * @return the progress of the request
+     */
+    public static TransferProgress track(Request request, Listener listener)
+    {
+        TransferProgress progress = new TransferProgress(listener);
+        progress.register(request);
+        return progress;
+    }
+
+    /**
+     * @return the progress of the upload of the request content
+     */
+    public Transfer getUpload()
+    {
+        return upload;
+    }
+
+    /**
+     * @return the progress of the download of the response content
+     */
+    public Transfer getDownload()
+    {
+        return download;
+    }
+
+    private void register(Request request)
+    {
+        request.onRequestBegin(r ->
+        {
+            Request.Content content = r.getBody();
+            upload.begin(content == null ? 0 : content.getLength());
+        });
+        request.onRequestContent((r, content) ->
+        {
+            upload.add(content.remaining());
+            notifyUpload(r);
+        });
+        request.onRequestSuccess(r ->
+        {
+            upload.end();
+            notifyUpload(r);
+        });
+        request.onResponseHeaders(r ->
+        {
+            // The Content-Length is that of the encoded content,
+            // while the listeners receive the decoded content.
+            long length = -1;
+            if (!r.getHeaders().contains(HttpHeader.CONTENT_ENCODING))
+                length = r.getHeaders().getLongField(HttpHeader.CONTENT_LENGTH);
+            download.begin(length);
+        });
+        request.onResponseContent((r, content) ->
+        {
+            download.add(content.remaining());
+            notifyDownload(r);
+        });
+        request.onResponseSuccess(r ->
+        {
+            download.end();
+            notifyDownload(r);
+        });
+    }
+
+    private void notifyUpload(Request request)
+    {
+        try
+        {
+            listener.onUploadProgress(request, upload);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyDownload(Response response)
+    {
+        try
+        {
+            listener.onDownloadProgress(response, download);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[up=%s,down=%s]", getClass().getSimpleName(), hashCode(), upload, download);
+    }
+
+    /**
+     * <p>The progress of the transfer of a content in one direction.</p>
+     */
+    public static class Transfer
+    {
+        private final AtomicLong bytes = new AtomicLong();
+        private volatile long totalBytes = -1;
+        private volatile long beginNanoTime;
+        private volatile long endNanoTime;
+        private volatile boolean complete;
+
+        private void begin(long totalBytes)
+        {
+            bytes.set(0);
+            this.totalBytes = totalBytes;
+            this.complete = false;
+            this.beginNanoTime = NanoTime.now();
+        }
+
+        private void add(long length)
+        {
+            bytes.addAndGet(length);
+        }
+
+        private void end()
+        {
+            endNanoTime = NanoTime.now();
+            complete = true;
+        }
+
+        /**
+         * @return the number of bytes transferred so far
+         */
+        public long getBytes()
+        {
+            return bytes.get();
+        }
+
+        /**
+         * @return the total number of bytes to transfer, or -1 if unknown
+         */
+        public long getTotalBytes()
+        {
+            return totalBytes;
+        }
+
+        /**
+         * @return the fraction, between 0 and 1, of the bytes transferred so far, or -1 if the total is unknown
+         */
+        public double getFraction()
+        {
+            if (complete)
+                return 1.0D;
+            long total = getTotalBytes();
+            if (total < 0)
+                return -1.0D;
+            if (total == 0)
+                return 0.0D;
+            return Math.min(1.0D, (double)getBytes() / total);
+        }
+
+        /**
+         * @return whether the transfer is complete
+         */
+        public boolean isComplete()
+        {
+            return complete;
+        }
+
+        /**
+         * @return the time elapsed since the transfer began, in milliseconds
+         */
+        public long getElapsedTime()
+        {
+            long begin = beginNanoTime;
+            if (begin == 0)
+                return 0;
+            return complete ? NanoTime.millisElapsed(begin, endNanoTime) : NanoTime.millisSince(begin);
+        }
+
+        /**
+         * @return the average throughput since the transfer began, in bytes per second
+         */
+        public long getThroughput()
+        {
+            long begin = beginNanoTime;
+            if (begin == 0)
+                return 0;
+            long elapsed = complete ? NanoTime.elapsed(begin, endNanoTime) : NanoTime.since(begin);
+            if (elapsed <= 0)
+                return 0;
+            return getBytes() * TimeUnit.SECONDS.toNanos(1) / elapsed;
+        }
+
+        /**
+         * <p>Estimates the time remaining to complete the transfer, based on the current throughput.</p>
+         *
+         * @return the estimated time remaining in milliseconds, or -1 if it cannot be estimated
+         */
+        public long getEstimatedRemainingTime()
+        {
+            if (complete)
+                return 0;
+            long total = getTotalBytes();
+            long throughput = getThroughput();
+            if (total < 0 || throughput <= 0)
+                return -1;
+            long remaining = Math.max(0, total - getBytes());
+            return remaining * 1000 / throughput;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%d/%d@%dB/s", getBytes(), getTotalBytes(), getThroughput());
+        }
+    }
+
+    /**
+     * <p>A listener of the progress of the upload and download of a request.</p>
+     * <p>Listeners are notified every time a content buffer is transferred,
+     * and when the transfer completes.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when request content has been sent.</p>
+         *
+         * @param request the request
+         * @param upload the progress of the upload
+         */
+        public default void onUploadProgress(Request request, Transfer upload)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when response content has been received.</p>
+         *
+         * @param response the response
+         * @param download the progress of the download
+         */
+        public default void onDownloadProgress(Response response, Transfer download)
+        {
+        }
+    }
+}
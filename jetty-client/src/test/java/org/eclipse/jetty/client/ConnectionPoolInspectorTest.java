@@ -0,0 +1,116 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.util.List;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.util.FutureResponseListener;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class ConnectionPoolInspectorTest extends AbstractHttpClientServerTest
+{
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testInspectAndQuarantine(Scenario scenario) throws Exception
+    {
+        CountDownLatch received = new CountDownLatch(1);
+        CountDownLatch latch = new CountDownLatch(1);
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws InterruptedException
+            {
+                if (target.equals("/block"))
+                {
+                    received.countDown();
+                    latch.await(5, TimeUnit.SECONDS);
+                }
+            }
+        });
+        client.setMaxConnectionsPerDestination(1);
+        ConnectionPoolInspector inspector = new ConnectionPoolInspector(client);
+
+        FutureResponseListener blocked = new FutureResponseListener(client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/block"));
+        blocked.getRequest().send(blocked);
+        assertThat(received.await(5, TimeUnit.SECONDS), is(true));
+        FutureResponseListener queued = new FutureResponseListener(client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/queued"));
+        queued.getRequest().send(queued);
+
+        await().atMost(5, TimeUnit.SECONDS).until(() -> inspector.getDestinations().stream()
+            .anyMatch(destination -> destination.getConnections().size() == 1 && destination.getQueuedRequests().size() == 1));
+
+        List<ConnectionPoolInspector.DestinationInfo> destinations = inspector.getDestinations();
+        assertThat(destinations.size(), is(1));
+        ConnectionPoolInspector.DestinationInfo destination = destinations.get(0);
+        assertThat(destination.getMaxConnectionCount(), is(1));
+        ConnectionPoolInspector.RequestInfo request = destination.getQueuedRequests().get(0);
+        assertThat(request.getMethod(), is("GET"));
+        assertThat(request.getURI(), endsWith("/queued"));
+
+        ConnectionPoolInspector.ConnectionInfo connection = destination.getConnections().get(0);
+        assertThat(connection.getDestination(), is(destination.getOrigin()));
+        assertThat(connection.getProtocol(), is("http/1.1"));
+        assertThat(connection.isIdle(), is(false));
+        assertThat(connection.getActiveRequests(), is(1));
+        assertThat(connection.isQuarantined(), is(false));
+        if (scenario.getScheme().equals("https"))
+            assertThat(connection.getSSLSession(), notNullValue());
+        else
+            assertThat(connection.getSSLSession(), nullValue());
+        assertThat(inspector.inspect(), containsString(connection.getId()));
+
+        // The quarantined connection completes the exchange in progress
+        // and then it is closed, so the queued request uses a new connection.
+        assertThat(inspector.quarantineConnection(connection.getId()), is(true));
+        assertThat(inspector.findConnection(connection.getId()).isQuarantined(), is(true));
+        latch.countDown();
+
+        ContentResponse response = blocked.get(5, TimeUnit.SECONDS);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        response = queued.get(5, TimeUnit.SECONDS);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(connection.getConnection().isClosed(), is(true));
+
+        await().atMost(5, TimeUnit.SECONDS).until(() -> inspector.getConnections().stream().allMatch(ConnectionPoolInspector.ConnectionInfo::isIdle));
+        List<ConnectionPoolInspector.ConnectionInfo> connections = inspector.getConnections();
+        assertThat(connections.size(), is(1));
+        ConnectionPoolInspector.ConnectionInfo replacement = connections.get(0);
+        assertThat(replacement.getId(), not(is(connection.getId())));
+        assertThat(replacement.isQuarantined(), is(false));
+
+        assertThat(inspector.close(ConnectionPoolInspector.ConnectionInfo::isIdle), is(1));
+        await().atMost(5, TimeUnit.SECONDS).until(() -> inspector.getConnections().isEmpty());
+    }
+}
@@ -45,6 +45,7 @@ import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.ServerConnector;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Pool;
 import org.eclipse.jetty.util.Promise;
 import org.eclipse.jetty.util.SocketAddressResolver;
 import org.eclipse.jetty.util.thread.QueuedThreadPool;
@@ -71,16 +72,19 @@ public class ConnectionPoolTest
         pool.setMaxDuration(10);
         return pool;
     });
+    private static final ConnectionPoolFactory DUPLEX_STRIPED = new ConnectionPoolFactory("duplex-striped", destination -> new DuplexConnectionPool(destination, Pool.StrategyType.STRIPED, destination.getHttpClient().getMaxConnectionsPerDestination(), false, destination));
     private static final ConnectionPoolFactory ROUND_ROBIN = new ConnectionPoolFactory("round-robin", destination -> new RoundRobinConnectionPool(destination, destination.getHttpClient().getMaxConnectionsPerDestination(), destination));
+    private static final ConnectionPoolFactory LEAST_LOADED = new ConnectionPoolFactory("least-loaded", destination -> new LeastLoadedConnectionPool(destination, destination.getHttpClient().getMaxConnectionsPerDestination(), destination, 1));
+    private static final ConnectionPoolFactory AFFINITY = new ConnectionPoolFactory("affinity", destination -> new AffinityConnectionPool(destination, destination.getHttpClient().getMaxConnectionsPerDestination(), destination, 1, "affinity"));
 
     public static Stream<ConnectionPoolFactory> pools()
     {
-        return Stream.of(DUPLEX, MULTIPLEX, RANDOM, DUPLEX_MAX_DURATION, ROUND_ROBIN);
+        return Stream.of(DUPLEX, MULTIPLEX, RANDOM, DUPLEX_MAX_DURATION, DUPLEX_STRIPED, ROUND_ROBIN, LEAST_LOADED, AFFINITY);
     }
 
     public static Stream<ConnectionPoolFactory> poolsNoRoundRobin()
     {
-        return Stream.of(DUPLEX, MULTIPLEX, RANDOM, DUPLEX_MAX_DURATION);
+        return Stream.of(DUPLEX, MULTIPLEX, RANDOM, DUPLEX_MAX_DURATION, DUPLEX_STRIPED, LEAST_LOADED, AFFINITY);
     }
 
     private Server server;
@@ -649,6 +653,112 @@ public class ConnectionPoolTest
         assertEquals(0, connectionPool.getConnectionCount());
     }
 
+    @Test
+    public void testAffinity() throws Exception
+    {
+        int maxConnections = 4;
+        List<Object> preferred = new CopyOnWriteArrayList<>();
+        startServer(new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getWriter().print(request.getRemotePort());
+            }
+        });
+        startClient(destination ->
+        {
+            try
+            {
+                AffinityConnectionPool connectionPool = new AffinityConnectionPool(destination, maxConnections, destination, 1, "user");
+                connectionPool.addListener(new AffinityConnectionPool.Listener()
+                {
+                    @Override
+                    public void onAffinity(AffinityConnectionPool pool, Object key, Connection connection, boolean isPreferred)
+                    {
+                        if (isPreferred)
+                            preferred.add(key);
+                    }
+                });
+                connectionPool.preCreateConnections(maxConnections).get();
+                return connectionPool;
+            }
+            catch (Exception x)
+            {
+                throw new RuntimeException(x);
+            }
+        });
+
+        int users = 8;
+        int requests = 5;
+        for (int u = 0; u < users; ++u)
+        {
+            String user = "user-" + u;
+            String port = null;
+            for (int r = 0; r < requests; ++r)
+            {
+                ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+                    .attribute("user", user)
+                    .timeout(5, TimeUnit.SECONDS)
+                    .send();
+                assertEquals(HttpStatus.OK_200, response.getStatus());
+                // All the requests of a user are sent over the same connection.
+                if (port == null)
+                    port = response.getContentAsString();
+                else
+                    assertEquals(port, response.getContentAsString());
+            }
+        }
+        assertThat(preferred.size(), is(users * requests));
+
+        // Requests without affinity key are sent over any connection.
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertThat(preferred.size(), is(users * requests));
+    }
+
+    @Test
+    public void testListener() throws Exception
+    {
+        AtomicInteger creating = new AtomicInteger();
+        List<Connection> acquired = new CopyOnWriteArrayList<>();
+        startServer(new EmptyServerHandler());
+        startClient(destination ->
+        {
+            LeastLoadedConnectionPool connectionPool = new LeastLoadedConnectionPool(destination, 2, destination, 1);
+            connectionPool.addListener(new AbstractConnectionPool.Listener()
+            {
+                @Override
+                public void onCreating(AbstractConnectionPool pool)
+                {
+                    creating.incrementAndGet();
+                }
+
+                @Override
+                public void onAcquired(AbstractConnectionPool pool, Connection connection)
+                {
+                    acquired.add(connection);
+                }
+            });
+            return connectionPool;
+        });
+
+        for (int i = 0; i < 3; ++i)
+        {
+            ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+        }
+
+        // Sequential requests reuse the same connection.
+        assertThat(creating.get(), is(1));
+        assertThat(acquired.size(), is(3));
+        assertThat(acquired.stream().distinct().count(), is(1L));
+    }
+
     private static class ConnectionPoolFactory
     {
         private final String name;
@@ -0,0 +1,321 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.Random;
+import java.util.concurrent.CancellationException;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.Flow;
+import java.util.concurrent.SubmissionPublisher;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.client.util.FutureResponseListener;
+import org.eclipse.jetty.client.util.PublisherRequestContent;
+import org.eclipse.jetty.client.util.PublisherResponseListener;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class HttpClientFlowTest extends AbstractHttpClientServerTest
+{
+    private void startEcho(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                IO.copy(request.getInputStream(), response.getOutputStream());
+            }
+        });
+    }
+
+    private void startDownload(Scenario scenario, byte[] data) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.setContentLength(data.length);
+                // Write in chunks, so that the client receives multiple buffers.
+                for (int offset = 0; offset < data.length; offset += 1024)
+                {
+                    response.getOutputStream().write(data, offset, Math.min(1024, data.length - offset));
+                    response.flushBuffer();
+                }
+            }
+        });
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testUploadFromPublisher(Scenario scenario) throws Exception
+    {
+        startEcho(scenario);
+
+        SubmissionPublisher<ByteBuffer> publisher = new SubmissionPublisher<>();
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .body(new PublisherRequestContent("text/plain", publisher));
+        FutureResponseListener listener = new FutureResponseListener(request);
+        request.send(listener);
+
+        // SubmissionPublisher drops items submitted before there are subscribers.
+        await().atMost(5, TimeUnit.SECONDS).until(publisher::getNumberOfSubscribers, is(1));
+        publisher.submit(BufferUtil.toBuffer("hello"));
+        publisher.submit(BufferUtil.toBuffer(" "));
+        publisher.submit(BufferUtil.toBuffer("world"));
+        publisher.close();
+
+        ContentResponse response = listener.get(5, TimeUnit.SECONDS);
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("hello world", response.getContentAsString());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testPublisherFailureFailsRequest(Scenario scenario) throws Exception
+    {
+        startEcho(scenario);
+
+        SubmissionPublisher<ByteBuffer> publisher = new SubmissionPublisher<>();
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .body(new PublisherRequestContent(publisher));
+        FutureResponseListener listener = new FutureResponseListener(request);
+        request.send(listener);
+
+        await().atMost(5, TimeUnit.SECONDS).until(publisher::getNumberOfSubscribers, is(1));
+        publisher.submit(BufferUtil.toBuffer("hello"));
+        Throwable failure = new IOException("explicitly_thrown_by_test");
+        publisher.closeExceptionally(failure);
+
+        ExecutionException x = assertThrows(ExecutionException.class, () -> listener.get(5, TimeUnit.SECONDS));
+        assertThat(x.getCause(), is(failure));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testDownloadToSubscriber(Scenario scenario) throws Exception
+    {
+        byte[] data = new byte[64 * 1024];
+        new Random().nextBytes(data);
+        startDownload(scenario, data);
+
+        PublisherResponseListener listener = new PublisherResponseListener();
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        CountDownLatch latch = new CountDownLatch(1);
+        AtomicReference<Throwable> failureRef = new AtomicReference<>();
+        listener.subscribe(new Flow.Subscriber<>()
+        {
+            private Flow.Subscription subscription;
+
+            @Override
+            public void onSubscribe(Flow.Subscription subscription)
+            {
+                this.subscription = subscription;
+                subscription.request(1);
+            }
+
+            @Override
+            public void onNext(ByteBuffer item)
+            {
+                output.write(item.array(), item.arrayOffset() + item.position(), item.remaining());
+                subscription.request(1);
+            }
+
+            @Override
+            public void onError(Throwable failure)
+            {
+                failureRef.set(failure);
+                latch.countDown();
+            }
+
+            @Override
+            public void onComplete()
+            {
+                latch.countDown();
+            }
+        });
+
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertThat(failureRef.get(), nullValue());
+        assertArrayEquals(data, output.toByteArray());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testNoSubscriberDemandStallsDownload(Scenario scenario) throws Exception
+    {
+        byte[] data = new byte[64 * 1024];
+        startDownload(scenario, data);
+
+        PublisherResponseListener listener = new PublisherResponseListener();
+        CountDownLatch complete = new CountDownLatch(1);
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .onComplete(result -> complete.countDown())
+            .send(listener);
+
+        // Without a subscriber there is no demand, so the response cannot complete.
+        assertThat(complete.await(1, TimeUnit.SECONDS), is(false));
+
+        CountDownLatch latch = new CountDownLatch(1);
+        listener.subscribe(new NoopSubscriber()
+        {
+            @Override
+            public void onSubscribe(Flow.Subscription subscription)
+            {
+                subscription.request(Long.MAX_VALUE);
+            }
+
+            @Override
+            public void onComplete()
+            {
+                latch.countDown();
+            }
+        });
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertTrue(complete.await(5, TimeUnit.SECONDS));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testCancelSubscriptionAbortsResponse(Scenario scenario) throws Exception
+    {
+        byte[] data = new byte[64 * 1024];
+        startDownload(scenario, data);
+
+        PublisherResponseListener listener = new PublisherResponseListener();
+        CountDownLatch terminated = new CountDownLatch(1);
+        listener.subscribe(new Flow.Subscriber<>()
+        {
+            private Flow.Subscription subscription;
+
+            @Override
+            public void onSubscribe(Flow.Subscription subscription)
+            {
+                this.subscription = subscription;
+                subscription.request(1);
+            }
+
+            @Override
+            public void onNext(ByteBuffer item)
+            {
+                subscription.cancel();
+            }
+
+            @Override
+            public void onError(Throwable failure)
+            {
+                terminated.countDown();
+            }
+
+            @Override
+            public void onComplete()
+            {
+                terminated.countDown();
+            }
+        });
+
+        CountDownLatch latch = new CountDownLatch(1);
+        AtomicReference<Result> resultRef = new AtomicReference<>();
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .onComplete(result ->
+            {
+                resultRef.set(result);
+                latch.countDown();
+            })
+            .send(listener);
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertThat(resultRef.get().getResponseFailure(), instanceOf(CancellationException.class));
+        // A cancelled subscriber is not notified of the termination.
+        assertThat(terminated.await(500, TimeUnit.MILLISECONDS), is(false));
+    }
+
+    @Test
+    public void testSecondSubscriberRejected()
+    {
+        PublisherResponseListener listener = new PublisherResponseListener();
+        listener.subscribe(new NoopSubscriber());
+        AtomicReference<Throwable> failureRef = new AtomicReference<>();
+        listener.subscribe(new NoopSubscriber()
+        {
+            @Override
+            public void onError(Throwable failure)
+            {
+                failureRef.set(failure);
+            }
+        });
+        assertThat(failureRef.get(), instanceOf(IllegalStateException.class));
+    }
+
+    private static class NoopSubscriber implements Flow.Subscriber<ByteBuffer>
+    {
+        @Override
+        public void onSubscribe(Flow.Subscription subscription)
+        {
+        }
+
+        @Override
+        public void onNext(ByteBuffer item)
+        {
+        }
+
+        @Override
+        public void onError(Throwable failure)
+        {
+        }
+
+        @Override
+        public void onComplete()
+        {
+        }
+    }
+}
@@ -0,0 +1,138 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.time.Duration;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.http.HttpClientTransportOverHTTP;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class HttpClientHappyEyeballsTest extends AbstractHttpClientServerTest
+{
+    @Test
+    public void testSortAddresses() throws Exception
+    {
+        InetSocketAddress ipv6a = new InetSocketAddress(InetAddress.getByName("2001:db8::1"), 80);
+        InetSocketAddress ipv6b = new InetSocketAddress(InetAddress.getByName("2001:db8::2"), 80);
+        InetSocketAddress ipv4a = new InetSocketAddress(InetAddress.getByName("192.0.2.1"), 80);
+        InetSocketAddress ipv4b = new InetSocketAddress(InetAddress.getByName("192.0.2.2"), 80);
+
+        List<InetSocketAddress> sorted = AbstractConnectorHttpClientTransport.sortAddresses(List.of(ipv6a, ipv6b, ipv4a, ipv4b));
+        assertThat(sorted, contains(ipv6a, ipv4a, ipv6b, ipv4b));
+
+        sorted = AbstractConnectorHttpClientTransport.sortAddresses(List.of(ipv4a, ipv4b, ipv6a));
+        assertThat(sorted, contains(ipv4a, ipv6a, ipv4b));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testSlowFirstAddressLosesRace(Scenario scenario) throws Exception
+    {
+        startServer(scenario, new EmptyServerHandler());
+
+        // Two distinct instances for the same address, the first one slow to connect.
+        InetSocketAddress slow = new InetSocketAddress("127.0.0.1", connector.getLocalPort());
+        InetSocketAddress fast = new InetSocketAddress("127.0.0.1", connector.getLocalPort());
+        long slowDelay = 5000;
+        QueuedThreadPool clientThreads = new QueuedThreadPool();
+        clientThreads.setName("client");
+        ClientConnector clientConnector = new ClientConnector()
+        {
+            @Override
+            public void connect(SocketAddress address, Map<String, Object> context)
+            {
+                if (address == slow)
+                    getScheduler().schedule(() -> super.connect(address, context), slowDelay, TimeUnit.MILLISECONDS);
+                else
+                    super.connect(address, context);
+            }
+        };
+        clientConnector.setExecutor(clientThreads);
+        clientConnector.setSslContextFactory(scenario.newClientSslContextFactory());
+        HttpClientTransportOverHTTP transport = new HttpClientTransportOverHTTP(clientConnector);
+        transport.setHappyEyeballs(true);
+        transport.setConnectionAttemptDelay(Duration.ofMillis(100));
+        client = new HttpClient(transport);
+        client.setSocketAddressResolver(new SocketAddressResolver()
+        {
+            @Override
+            public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+            {
+                promise.succeeded(List.of(slow, fast));
+            }
+        });
+        client.start();
+
+        long begin = NanoTime.now();
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(slowDelay * 2, TimeUnit.MILLISECONDS);
+        ContentResponse response = request.send();
+
+        assertEquals(200, response.getStatus());
+        assertThat(NanoTime.millisSince(begin), lessThan(slowDelay));
+        assertThat(request.getConnection().getRemoteSocketAddress(), is(fast));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testFailedFirstAddressStartsNextAttempt(Scenario scenario) throws Exception
+    {
+        startServer(scenario, new EmptyServerHandler());
+        startClient(scenario, httpClient ->
+        {
+            AbstractConnectorHttpClientTransport transport = (AbstractConnectorHttpClientTransport)httpClient.getTransport();
+            transport.setHappyEyeballs(true);
+            // A long delay, so the second attempt is started by the failure of the first.
+            transport.setConnectionAttemptDelay(Duration.ofSeconds(30));
+            httpClient.setSocketAddressResolver(new SocketAddressResolver()
+            {
+                @Override
+                public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+                {
+                    // Port 1 on the loopback interface is normally closed.
+                    promise.succeeded(List.of(new InetSocketAddress("127.0.0.1", 1), new InetSocketAddress("127.0.0.1", port)));
+                }
+            });
+        });
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(200, response.getStatus());
+    }
+}
@@ -0,0 +1,184 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import java.util.Locale;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.function.LongConsumer;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class HttpClientInterceptorTest extends AbstractHttpClientServerTest
+{
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testInterceptorsModifyRequestInOrder(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getWriter().print(request.getHeader("X-Interceptor"));
+            }
+        });
+
+        List<String> invocations = new CopyOnWriteArrayList<>();
+        client.addInterceptor((request, listener, chain) ->
+        {
+            invocations.add("first");
+            request.headers(headers -> headers.put("X-Interceptor", "first"));
+            chain.proceed(request, listener);
+        });
+        client.addInterceptor((request, listener, chain) ->
+        {
+            invocations.add("second");
+            request.headers(headers -> headers.put("X-Interceptor", headers.get("X-Interceptor") + ",second"));
+            chain.proceed(request, listener);
+        });
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("first,second", response.getContentAsString());
+        assertEquals(List.of("first", "second"), invocations);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testInterceptorShortCircuitsWithSyntheticResponse(Scenario scenario) throws Exception
+    {
+        AtomicInteger serverRequests = new AtomicInteger();
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                serverRequests.incrementAndGet();
+            }
+        });
+
+        client.addInterceptor((request, listener, chain) ->
+        {
+            if (request.getPath().startsWith("/cached"))
+            {
+                HttpFields headers = HttpFields.build().put(HttpHeader.CONTENT_TYPE, "text/plain");
+                Interceptor.respond(request, listener, HttpStatus.OK_200, headers, BufferUtil.toBuffer("cached", StandardCharsets.UTF_8));
+            }
+            else
+            {
+                chain.proceed(request, listener);
+            }
+        });
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/cached")
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("text/plain", response.getHeaders().get(HttpHeader.CONTENT_TYPE));
+        assertEquals("cached", response.getContentAsString());
+        assertEquals(0, serverRequests.get());
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/other")
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(1, serverRequests.get());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testInterceptorWrapsResponseContent(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getWriter().print("hello");
+            }
+        });
+
+        client.addInterceptor((request, listener, chain) -> chain.proceed(request, new Interceptor.ListenerWrapper(listener)
+        {
+            @Override
+            public void onContent(Response response, LongConsumer demand, ByteBuffer content, Callback callback)
+            {
+                String upperCase = BufferUtil.toString(content, StandardCharsets.UTF_8).toUpperCase(Locale.ENGLISH);
+                super.onContent(response, demand, BufferUtil.toBuffer(upperCase, StandardCharsets.UTF_8), callback);
+            }
+        }));
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("HELLO", response.getContentAsString());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testInterceptorFailureFailsRequest(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler());
+
+        client.addInterceptor((request, listener, chain) ->
+        {
+            throw new IllegalStateException("explicitly_thrown_by_test");
+        });
+
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send());
+
+        assertThat(failure.getCause(), instanceOf(IllegalStateException.class));
+        assertThat(client.removeInterceptor(client.getInterceptors().get(0)), is(true));
+        assertThat(client.getInterceptors().isEmpty(), is(true));
+    }
+}
@@ -0,0 +1,170 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.http.HttpConnectionOverHTTP;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.HttpConnectionFactory;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+
+public class HttpClientKeepAliveTest extends AbstractHttpClientServerTest
+{
+    private final List<Integer> ports = new CopyOnWriteArrayList<>();
+    private volatile String keepAlive;
+
+    private EmptyServerHandler newServerHandler()
+    {
+        return new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                ports.add(request.getRemotePort());
+                if (keepAlive != null)
+                    response.setHeader(HttpHeader.KEEP_ALIVE.asString(), keepAlive);
+            }
+        };
+    }
+
+    private Request newRequest(Scenario scenario)
+    {
+        return client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS);
+    }
+
+    private AbstractConnectionPool getConnectionPool(Request request)
+    {
+        return (AbstractConnectionPool)((HttpDestination)client.resolveDestination(request)).getConnectionPool();
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testKeepAliveMaxZeroClosesConnection(Scenario scenario) throws Exception
+    {
+        keepAlive = "timeout=30, max=0";
+        start(scenario, newServerHandler());
+
+        for (int i = 0; i < 2; ++i)
+        {
+            ContentResponse response = newRequest(scenario).send();
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        }
+
+        assertThat(ports.size(), is(2));
+        assertThat(ports.get(1), not(is(ports.get(0))));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testKeepAliveHintsDisabled(Scenario scenario) throws Exception
+    {
+        keepAlive = "timeout=30, max=0";
+        startServer(scenario, newServerHandler());
+        startClient(scenario, httpClient -> httpClient.setKeepAliveHintsEnabled(false));
+
+        for (int i = 0; i < 2; ++i)
+        {
+            ContentResponse response = newRequest(scenario).send();
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        }
+
+        assertThat(ports.size(), is(2));
+        assertThat(ports.get(1), is(ports.get(0)));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testKeepAliveTimeoutLowersIdleTimeout(Scenario scenario) throws Exception
+    {
+        keepAlive = "timeout=1";
+        start(scenario, newServerHandler());
+
+        Request request = newRequest(scenario);
+        ContentResponse response = request.send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        // The client idle timeout is much longer, but the connection
+        // is closed before the server Keep-Alive timeout expires.
+        AbstractConnectionPool connectionPool = getConnectionPool(request);
+        HttpConnectionOverHTTP connection = (HttpConnectionOverHTTP)connectionPool.getIdleConnections().peek();
+        assertThat(connection.getEndPoint().getIdleTimeout(), is(500L));
+        await().atMost(5, TimeUnit.SECONDS).until(connectionPool::getConnectionCount, is(0));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testServerMaxRequestsPerConnection(Scenario scenario) throws Exception
+    {
+        start(scenario, newServerHandler());
+        connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().setMaxRequestsPerConnection(2);
+
+        for (int i = 0; i < 4; ++i)
+        {
+            ContentResponse response = newRequest(scenario).send();
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        }
+
+        assertThat(ports.size(), is(4));
+        assertThat(ports.get(1), is(ports.get(0)));
+        assertThat(ports.get(2), not(is(ports.get(1))));
+        assertThat(ports.get(3), is(ports.get(2)));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testConnectionReplacedBeforeMaxDuration(Scenario scenario) throws Exception
+    {
+        startServer(scenario, newServerHandler());
+        startClient(scenario, httpClient ->
+        {
+            httpClient.setMaxConnectionDuration(2000);
+            httpClient.setConnectionReplacementMargin(1500);
+        });
+
+        Request request = newRequest(scenario);
+        assertThat(request.send().getStatus(), is(HttpStatus.OK_200));
+        AbstractConnectionPool connectionPool = getConnectionPool(request);
+        assertThat(connectionPool.getMaxDuration(), is(2000L));
+        assertThat(connectionPool.getReplacementMargin(), is(1500L));
+
+        // Wait until the connection is within the replacement margin.
+        Thread.sleep(750);
+
+        assertThat(newRequest(scenario).send().getStatus(), is(HttpStatus.OK_200));
+        // The connection has been replaced when released.
+        await().atMost(5, TimeUnit.SECONDS).until(connectionPool::getIdleConnectionCount, is(1));
+        assertThat(connectionPool.getConnectionCount(), is(1));
+
+        assertThat(newRequest(scenario).send().getStatus(), is(HttpStatus.OK_200));
+        assertThat(ports.size(), is(3));
+        assertThat(ports.get(1), is(ports.get(0)));
+        assertThat(ports.get(2), not(is(ports.get(1))));
+    }
+}
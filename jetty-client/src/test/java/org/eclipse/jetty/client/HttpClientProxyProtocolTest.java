@@ -17,6 +17,7 @@ import java.io.IOException;
 import java.nio.charset.StandardCharsets;
 import java.util.Collections;
 import java.util.List;
+import java.util.Map;
 import java.util.concurrent.ThreadLocalRandom;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
@@ -31,6 +32,7 @@ import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.server.Handler;
 import org.eclipse.jetty.server.HttpConnectionFactory;
 import org.eclipse.jetty.server.ProxyConnectionFactory;
+import org.eclipse.jetty.server.ProxyCustomizer;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.ServerConnector;
@@ -40,6 +42,7 @@ import org.junit.jupiter.api.Test;
 
 import static org.eclipse.jetty.client.ProxyProtocolClientConnectionFactory.V1;
 import static org.eclipse.jetty.client.ProxyProtocolClientConnectionFactory.V2;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
 import static org.junit.jupiter.api.Assertions.assertEquals;
 import static org.junit.jupiter.api.Assertions.assertNotNull;
 import static org.junit.jupiter.api.Assertions.assertSame;
@@ -215,6 +218,50 @@ public class HttpClientProxyProtocolTest
         assertEquals(2, client.getDestinations().size());
     }
 
+    @Test
+    public void testClientProxyProtocolV2WithStandardVectors() throws Exception
+    {
+        startServer(new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                assertEquals("h2", request.getAttribute(ProxyCustomizer.ALPN_ATTRIBUTE_NAME));
+                assertEquals("example.com", request.getAttribute(ProxyCustomizer.AUTHORITY_ATTRIBUTE_NAME));
+                assertArrayEquals(new byte[]{1, 2, 3}, (byte[])request.getAttribute(ProxyCustomizer.UNIQUE_ID_ATTRIBUTE_NAME));
+                assertEquals("TLSv1.3", request.getAttribute(ProxyCustomizer.TLS_VERSION_ATTRIBUTE_NAME));
+                assertEquals("client", request.getAttribute(ProxyCustomizer.TLS_CN_ATTRIBUTE_NAME));
+                assertEquals("TLS_AES_128_GCM_SHA256", request.getAttribute(ProxyCustomizer.TLS_CIPHER_ATTRIBUTE_NAME));
+                assertEquals(Boolean.TRUE, request.getAttribute(ProxyCustomizer.TLS_CLIENT_VERIFIED_ATTRIBUTE_NAME));
+                @SuppressWarnings("unchecked")
+                Map<Integer, byte[]> tlvs = (Map<Integer, byte[]>)request.getAttribute(ProxyCustomizer.TLVS_ATTRIBUTE_NAME);
+                assertEquals("custom", new String(tlvs.get(0xE0), StandardCharsets.US_ASCII));
+                assertTrue(Collections.list(request.getAttributeNames()).contains(ProxyCustomizer.TLS_CN_ATTRIBUTE_NAME));
+            }
+        });
+        connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().addCustomizer(new ProxyCustomizer());
+        startClient();
+
+        V2.Tag.TLV ssl = V2.Tag.TLV.ssl(V2.Tag.TLV.PP2_CLIENT_SSL | V2.Tag.TLV.PP2_CLIENT_CERT_CONN, 0, List.of(
+            new V2.Tag.TLV(V2.Tag.TLV.PP2_SUBTYPE_SSL_VERSION, "TLSv1.3".getBytes(StandardCharsets.US_ASCII)),
+            new V2.Tag.TLV(V2.Tag.TLV.PP2_SUBTYPE_SSL_CN, "client".getBytes(StandardCharsets.UTF_8)),
+            new V2.Tag.TLV(V2.Tag.TLV.PP2_SUBTYPE_SSL_CIPHER, "TLS_AES_128_GCM_SHA256".getBytes(StandardCharsets.US_ASCII))
+        ));
+        List<V2.Tag.TLV> tlvs = List.of(
+            V2.Tag.TLV.alpn("h2"),
+            V2.Tag.TLV.authority("example.com"),
+            V2.Tag.TLV.uniqueId(new byte[]{1, 2, 3}),
+            ssl,
+            new V2.Tag.TLV(0xE0, "custom".getBytes(StandardCharsets.US_ASCII))
+        );
+        V2.Tag tag = new V2.Tag("127.0.0.1", 12345, tlvs);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .tag(tag)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+    }
+
     @Test
     public void testProxyProtocolWrappingHTTPProxy() throws Exception
     {
@@ -14,9 +14,13 @@
 package org.eclipse.jetty.client;
 
 import java.io.IOException;
+import java.net.ServerSocket;
 import java.net.URI;
 import java.nio.charset.StandardCharsets;
 import java.util.Base64;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ExecutionException;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
 import javax.servlet.ServletException;
@@ -34,6 +38,9 @@ import org.junit.jupiter.params.ParameterizedTest;
 import org.junit.jupiter.params.provider.ArgumentsSource;
 
 import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
 
 public class HttpClientProxyTest extends AbstractHttpClientServerTest
 {
@@ -392,4 +399,141 @@ public class HttpClientProxyTest extends AbstractHttpClientServerTest
         assertEquals(status, response2.getStatus());
         assertEquals(1, requests.get());
     }
+
+    @ParameterizedTest
+    @ArgumentsSource(NonSslScenarioProvider.class) // Avoid TLS otherwise CONNECT requests are sent instead of proxied requests
+    public void testProxyFailover(Scenario scenario) throws Exception
+    {
+        String serverHost = "server";
+        int status = HttpStatus.NO_CONTENT_204;
+        start(scenario, new ProxyHandler(serverHost, status));
+
+        int proxyPort = connector.getLocalPort();
+        int serverPort = proxyPort + 1; // Any port will do for these tests - just not the same as the proxy
+        ProxyConfiguration proxyConfig = client.getProxyConfiguration();
+        HttpProxy deadProxy = new HttpProxy("localhost", freePort());
+        HttpProxy liveProxy = new HttpProxy("localhost", proxyPort);
+        proxyConfig.addProxy(deadProxy);
+        proxyConfig.addProxy(liveProxy);
+        List<ProxyConfiguration.Proxy> selected = new CopyOnWriteArrayList<>();
+        List<ProxyConfiguration.Proxy> skipped = new CopyOnWriteArrayList<>();
+        List<ProxyConfiguration.Proxy> failed = new CopyOnWriteArrayList<>();
+        proxyConfig.addListener(new ProxyConfiguration.Listener()
+        {
+            @Override
+            public void onSelected(Origin origin, ProxyConfiguration.Proxy proxy)
+            {
+                selected.add(proxy);
+            }
+
+            @Override
+            public void onSkipped(Origin origin, ProxyConfiguration.Proxy proxy)
+            {
+                skipped.add(proxy);
+            }
+
+            @Override
+            public void onFailed(ProxyConfiguration.Proxy proxy, Throwable failure)
+            {
+                failed.add(proxy);
+            }
+        });
+
+        ContentResponse response1 = client.newRequest(serverHost, serverPort)
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(status, response1.getStatus());
+        assertEquals(List.of(deadProxy, liveProxy), selected);
+        assertEquals(List.of(deadProxy), failed);
+        assertFalse(proxyConfig.isHealthy(deadProxy));
+        assertTrue(proxyConfig.isHealthy(liveProxy));
+
+        // The unhealthy proxy is now skipped.
+        selected.clear();
+        ContentResponse response2 = client.newRequest(serverHost, serverPort)
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(status, response2.getStatus());
+        assertEquals(List.of(liveProxy), selected);
+        assertEquals(List.of(deadProxy), skipped);
+        assertEquals(List.of(deadProxy), failed);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(NonSslScenarioProvider.class) // Avoid TLS otherwise CONNECT requests are sent instead of proxied requests
+    public void testProxyFailoverExhausted(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler());
+
+        ProxyConfiguration proxyConfig = client.getProxyConfiguration();
+        proxyConfig.addProxy(new HttpProxy("localhost", freePort()));
+        proxyConfig.addProxy(new HttpProxy("localhost", freePort()));
+
+        assertThrows(ExecutionException.class, () -> client.newRequest("server", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(NonSslScenarioProvider.class) // Avoid TLS otherwise CONNECT requests are sent instead of proxied requests
+    public void testRequestProxies(Scenario scenario) throws Exception
+    {
+        String serverHost = "server";
+        int status = HttpStatus.NO_CONTENT_204;
+        start(scenario, new ProxyHandler(serverHost, status));
+
+        int proxyPort = connector.getLocalPort();
+        int serverPort = proxyPort + 1; // Any port will do for these tests - just not the same as the proxy
+        HttpProxy deadProxy = new HttpProxy("localhost", freePort());
+        client.getProxyConfiguration().addProxy(deadProxy);
+        HttpProxy requestProxy = new HttpProxy("localhost", proxyPort);
+
+        ContentResponse response = client.newRequest(serverHost, serverPort)
+            .scheme(scenario.getScheme())
+            .proxies(List.of(requestProxy))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(status, response.getStatus());
+        assertEquals(requestProxy, ((HttpDestination)client.resolveDestination(response.getRequest())).getProxy());
+        // The configured proxy has not been tried.
+        assertTrue(client.getProxyConfiguration().isHealthy(deadProxy));
+    }
+
+    private static int freePort() throws IOException
+    {
+        try (ServerSocket server = new ServerSocket(0))
+        {
+            return server.getLocalPort();
+        }
+    }
+
+    private static class ProxyHandler extends AbstractHandler
+    {
+        private final String serverHost;
+        private final int status;
+
+        private ProxyHandler(String serverHost, int status)
+        {
+            this.serverHost = serverHost;
+            this.status = status;
+        }
+
+        @Override
+        public void handle(String target, org.eclipse.jetty.server.Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+        {
+            baseRequest.setHandled(true);
+            if (!URI.create(baseRequest.getHttpURI().toString()).isAbsolute())
+                response.setStatus(HttpServletResponse.SC_USE_PROXY);
+            else if (serverHost.equals(request.getServerName()))
+                response.setStatus(status);
+            else
+                response.setStatus(HttpServletResponse.SC_NOT_ACCEPTABLE);
+        }
+    }
 }
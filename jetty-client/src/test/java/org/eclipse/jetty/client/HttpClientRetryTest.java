@@ -0,0 +1,258 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.util.StringRequestContent;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class HttpClientRetryTest extends AbstractHttpClientServerTest
+{
+    private final List<String> events = new CopyOnWriteArrayList<>();
+    private RetryPolicy retryPolicy;
+
+    private void startRetry(Scenario scenario, int failures, int status) throws Exception
+    {
+        AtomicInteger requests = new AtomicInteger();
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                String content = IO.toString(request.getInputStream());
+                int attempt = requests.incrementAndGet();
+                response.setHeader("X-Attempt", String.valueOf(attempt));
+                if (attempt <= failures)
+                {
+                    if (status < 0)
+                    {
+                        // Close the connection without responding.
+                        jettyRequest.getHttpChannel().getEndPoint().close();
+                        return;
+                    }
+                    String retryAfter = request.getHeader("X-Retry-After");
+                    if (retryAfter != null)
+                        response.setHeader(HttpHeader.RETRY_AFTER.asString(), retryAfter);
+                    response.setStatus(status);
+                    response.getWriter().print("failure " + attempt);
+                    return;
+                }
+                response.getWriter().print(content);
+            }
+        });
+
+        retryPolicy = new RetryPolicy();
+        retryPolicy.setInitialDelay(10);
+        retryPolicy.setMaxDelay(100);
+        retryPolicy.addListener(new RetryPolicy.Listener()
+        {
+            @Override
+            public void onAttempt(Request request, int attempt)
+            {
+                events.add("attempt" + attempt);
+            }
+
+            @Override
+            public void onRetry(Request request, int attempt, Response response, Throwable failure, long delay)
+            {
+                events.add("retry" + attempt);
+            }
+
+            @Override
+            public void onExhausted(Request request, int attempt, Response response, Throwable failure)
+            {
+                events.add("exhausted" + attempt);
+            }
+        });
+        client.setRetryPolicy(retryPolicy);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRetryOnServiceUnavailable(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 2, HttpStatus.SERVICE_UNAVAILABLE_503);
+
+        AtomicInteger begins = new AtomicInteger();
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .onResponseBegin(r -> begins.incrementAndGet())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("3", response.getHeaders().get("X-Attempt"));
+        // The response listeners are only notified of the last attempt.
+        assertEquals(1, begins.get());
+        assertEquals(List.of("attempt1", "retry1", "attempt2", "retry2", "attempt3"), events);
+        assertEquals(2, retryPolicy.getRetries());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testMaxAttemptsExhausted(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, Integer.MAX_VALUE, HttpStatus.BAD_GATEWAY_502);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.BAD_GATEWAY_502, response.getStatus());
+        assertEquals("failure 3", response.getContentAsString());
+        assertEquals(List.of("attempt1", "retry1", "attempt2", "retry2", "attempt3", "exhausted3"), events);
+        assertEquals(1, retryPolicy.getExhaustedRetries());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRetryOnConnectionClosed(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 1, -1);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.PUT)
+            .body(new StringRequestContent("content"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content", response.getContentAsString());
+        assertEquals(List.of("attempt1", "retry1", "attempt2"), events);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testNonIdempotentNotRetried(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 2, HttpStatus.SERVICE_UNAVAILABLE_503);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .body(new StringRequestContent("content"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.SERVICE_UNAVAILABLE_503, response.getStatus());
+        assertEquals(List.of(), events);
+
+        // Enable retries of non-idempotent requests.
+        retryPolicy.setRetryNonIdempotent(true);
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .body(new StringRequestContent("content"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content", response.getContentAsString());
+        assertEquals(List.of("attempt1", "retry1", "attempt2"), events);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRetryAfter(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 1, HttpStatus.TOO_MANY_REQUESTS_429);
+
+        long begin = System.nanoTime();
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .headers(headers -> headers.put("X-Retry-After", "1"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin), greaterThanOrEqualTo(1000L));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRetryAfterTooLong(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 1, HttpStatus.SERVICE_UNAVAILABLE_503);
+        retryPolicy.setMaxRetryAfter(1000);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .headers(headers -> headers.put("X-Retry-After", "120"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.SERVICE_UNAVAILABLE_503, response.getStatus());
+        assertEquals("120", response.getHeaders().get(HttpHeader.RETRY_AFTER));
+        assertEquals(List.of("attempt1", "exhausted1"), events);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRetryBudget(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, Integer.MAX_VALUE, HttpStatus.SERVICE_UNAVAILABLE_503);
+        retryPolicy.setBudgetRatio(0);
+        retryPolicy.setBudgetCapacity(1);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.SERVICE_UNAVAILABLE_503, response.getStatus());
+        // Only one retry is allowed by the budget.
+        assertEquals(List.of("attempt1", "retry1", "attempt2", "exhausted2"), events);
+        assertThat(retryPolicy.getBudgetAvailable(), is(0.0D));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRequestWithoutRetryPolicy(Scenario scenario) throws Exception
+    {
+        startRetry(scenario, 1, HttpStatus.SERVICE_UNAVAILABLE_503);
+
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .retryPolicy(null)
+            .timeout(5, TimeUnit.SECONDS);
+        assertThat(request.getRetryPolicy(), nullValue());
+        ContentResponse response = request.send();
+
+        assertEquals(HttpStatus.SERVICE_UNAVAILABLE_503, response.getStatus());
+        assertEquals(List.of(), events);
+    }
+}
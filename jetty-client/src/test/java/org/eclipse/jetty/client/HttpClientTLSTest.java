@@ -30,6 +30,7 @@ import java.util.concurrent.ExecutionException;
 import java.util.concurrent.Executor;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.AtomicLong;
 import java.util.concurrent.atomic.AtomicReference;
 import javax.net.ssl.SSLEngine;
@@ -1451,4 +1452,40 @@ public class HttpClientTLSTest
         assertThat(clientStats.getReceivedBytes(), Matchers.greaterThan(0L));
         assertEquals(clientStats.getReceivedBytes(), serverStats.getSentBytes());
     }
+
+    @Test
+    public void testPreCreateConnections() throws Exception
+    {
+        startServer(createServerSslContextFactory(), new EmptyServerHandler());
+        startClient(createClientSslContextFactory());
+
+        AtomicInteger handshakes = new AtomicInteger();
+        client.addBean(new SslHandshakeListener()
+        {
+            @Override
+            public void handshakeSucceeded(Event event)
+            {
+                handshakes.incrementAndGet();
+            }
+        });
+
+        String uri = "https://localhost:" + connector.getLocalPort();
+        client.preCreateConnections(uri, 2).get(5, TimeUnit.SECONDS);
+
+        // The connections must be ready, with the TLS handshake completed.
+        assertEquals(2, handshakes.get());
+        HttpDestination destination = (HttpDestination)client.getDestinations().get(0);
+        AbstractConnectionPool connectionPool = (AbstractConnectionPool)destination.getConnectionPool();
+        assertEquals(2, connectionPool.getConnectionCount());
+        assertEquals(2, connectionPool.getIdleConnectionCount());
+
+        ContentResponse response = client.newRequest(uri)
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+
+        // No new connection must have been opened.
+        assertEquals(2, handshakes.get());
+        assertEquals(2, connectionPool.getConnectionCount());
+    }
 }
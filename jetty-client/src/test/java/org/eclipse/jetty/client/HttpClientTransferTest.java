@@ -0,0 +1,297 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.net.URI;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.Arrays;
+import java.util.List;
+import java.util.Random;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.util.BytesRequestContent;
+import org.eclipse.jetty.client.util.TransferProgress;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class HttpClientTransferTest extends AbstractHttpClientServerTest
+{
+    private static final String ETAG = "\"v1\"";
+
+    public WorkDir workDir;
+    private final List<String> ranges = new CopyOnWriteArrayList<>();
+    private byte[] data;
+
+    private void startDownload(Scenario scenario, int failures) throws Exception
+    {
+        data = new byte[64 * 1024];
+        new Random().nextBytes(data);
+        AtomicInteger requests = new AtomicInteger();
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                int attempt = requests.incrementAndGet();
+                response.setHeader(HttpHeader.ETAG.asString(), ETAG);
+                int offset = 0;
+                String range = request.getHeader(HttpHeader.RANGE.asString());
+                ranges.add(String.valueOf(range));
+                if (range != null && ETAG.equals(request.getHeader(HttpHeader.IF_RANGE.asString())))
+                {
+                    offset = Integer.parseInt(range.substring("bytes=".length(), range.length() - 1));
+                    if (offset >= data.length)
+                    {
+                        response.setHeader(HttpHeader.CONTENT_RANGE.asString(), "bytes */" + data.length);
+                        response.setStatus(HttpStatus.RANGE_NOT_SATISFIABLE_416);
+                        return;
+                    }
+                    response.setStatus(HttpStatus.PARTIAL_CONTENT_206);
+                    response.setHeader(HttpHeader.CONTENT_RANGE.asString(), "bytes " + offset + "-" + (data.length - 1) + "/" + data.length);
+                }
+                response.setContentLength(data.length - offset);
+                if (attempt <= failures)
+                {
+                    // Send part of the content, then close the connection.
+                    response.getOutputStream().write(data, offset, (data.length - offset) / 2);
+                    response.flushBuffer();
+                    jettyRequest.getHttpChannel().getEndPoint().close();
+                    return;
+                }
+                response.getOutputStream().write(data, offset, data.length - offset);
+            }
+        });
+    }
+
+    private ResumableDownload newResumableDownload(Scenario scenario, Path path)
+    {
+        RetryPolicy retryPolicy = new RetryPolicy();
+        retryPolicy.setInitialDelay(10);
+        retryPolicy.setMaxDelay(100);
+        ResumableDownload download = new ResumableDownload(client, URI.create(scenario.getScheme() + "://localhost:" + connector.getLocalPort() + "/file"), path);
+        download.setRetryPolicy(retryPolicy);
+        download.setRequestCustomizer(request -> request.timeout(5, TimeUnit.SECONDS));
+        return download;
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testResumableDownload(Scenario scenario) throws Exception
+    {
+        startDownload(scenario, 2);
+        Path path = workDir.getEmptyPathDir().resolve("file");
+
+        ResumableDownload download = newResumableDownload(scenario, path);
+        assertEquals(path, download.start().get(5, TimeUnit.SECONDS));
+
+        assertArrayEquals(data, Files.readAllBytes(path));
+        assertEquals(3, download.getAttempts());
+        assertEquals(2, download.getResumes());
+        assertEquals(ETAG, download.getETag());
+        assertEquals("null", ranges.get(0));
+        assertThat(ranges.get(1).startsWith("bytes="), is(true));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testResumableDownloadMaxAttempts(Scenario scenario) throws Exception
+    {
+        startDownload(scenario, Integer.MAX_VALUE);
+        Path path = workDir.getEmptyPathDir().resolve("file");
+
+        ResumableDownload download = newResumableDownload(scenario, path);
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> download.start().get(5, TimeUnit.SECONDS));
+        assertThat(failure.getCause(), instanceOf(IOException.class));
+        assertEquals(3, download.getAttempts());
+
+        // The partial content is kept, so that the download can be resumed later.
+        long length = Files.size(path);
+        assertTrue(length > 0 && length < data.length);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testResumeExistingFile(Scenario scenario) throws Exception
+    {
+        startDownload(scenario, 0);
+        Path path = workDir.getEmptyPathDir().resolve("file");
+        Files.write(path, Arrays.copyOf(data, 1000));
+
+        ResumableDownload download = newResumableDownload(scenario, path);
+        download.setETag(ETAG);
+        download.start().get(5, TimeUnit.SECONDS);
+
+        assertArrayEquals(data, Files.readAllBytes(path));
+        assertEquals(List.of("bytes=1000-"), ranges);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testResumeChangedResource(Scenario scenario) throws Exception
+    {
+        startDownload(scenario, 0);
+        Path path = workDir.getEmptyPathDir().resolve("file");
+        Files.write(path, new byte[1000]);
+
+        ResumableDownload download = newResumableDownload(scenario, path);
+        download.setETag("\"v0\"");
+        download.start().get(5, TimeUnit.SECONDS);
+
+        // The server ignored the range, and the whole content replaced the file.
+        assertArrayEquals(data, Files.readAllBytes(path));
+        assertEquals(ETAG, download.getETag());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testResumeCompleteFile(Scenario scenario) throws Exception
+    {
+        startDownload(scenario, 0);
+        Path path = workDir.getEmptyPathDir().resolve("file");
+        Files.write(path, data);
+
+        ResumableDownload download = newResumableDownload(scenario, path);
+        download.setETag(ETAG);
+        download.start().get(5, TimeUnit.SECONDS);
+
+        assertArrayEquals(data, Files.readAllBytes(path));
+        assertEquals(List.of("bytes=" + data.length + "-"), ranges);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testTransferProgress(Scenario scenario) throws Exception
+    {
+        byte[] upload = new byte[32 * 1024];
+        byte[] download = new byte[48 * 1024];
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                IO.readBytes(request.getInputStream());
+                response.setContentLength(download.length);
+                response.getOutputStream().write(download);
+            }
+        });
+
+        List<Long> uploads = new CopyOnWriteArrayList<>();
+        List<Long> downloads = new CopyOnWriteArrayList<>();
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .body(new BytesRequestContent(upload))
+            .timeout(5, TimeUnit.SECONDS);
+        TransferProgress progress = TransferProgress.track(request, new TransferProgress.Listener()
+        {
+            @Override
+            public void onUploadProgress(Request request, TransferProgress.Transfer upload)
+            {
+                uploads.add(upload.getBytes());
+            }
+
+            @Override
+            public void onDownloadProgress(Response response, TransferProgress.Transfer download)
+            {
+                downloads.add(download.getBytes());
+            }
+        });
+        ContentResponse response = request.send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        TransferProgress.Transfer up = progress.getUpload();
+        assertTrue(up.isComplete());
+        assertEquals(upload.length, up.getBytes());
+        assertEquals(upload.length, up.getTotalBytes());
+        assertThat(up.getFraction(), is(1.0D));
+        assertEquals(upload.length, (long)uploads.get(uploads.size() - 1));
+
+        TransferProgress.Transfer down = progress.getDownload();
+        assertTrue(down.isComplete());
+        assertEquals(download.length, down.getBytes());
+        assertEquals(download.length, down.getTotalBytes());
+        assertEquals(0, down.getEstimatedRemainingTime());
+        assertEquals(download.length, (long)downloads.get(downloads.size() - 1));
+        // Progress is reported incrementally.
+        for (int i = 1; i < downloads.size(); ++i)
+        {
+            assertTrue(downloads.get(i) >= downloads.get(i - 1));
+        }
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testTransferProgressUnknownLength(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getOutputStream().write(new byte[1024]);
+                response.flushBuffer();
+                response.getOutputStream().write(new byte[1024]);
+            }
+        });
+
+        Request request = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS);
+        List<Double> fractions = new CopyOnWriteArrayList<>();
+        TransferProgress progress = TransferProgress.track(request, new TransferProgress.Listener()
+        {
+            @Override
+            public void onDownloadProgress(Response response, TransferProgress.Transfer download)
+            {
+                if (!download.isComplete())
+                    fractions.add(download.getFraction());
+            }
+        });
+        request.send();
+
+        TransferProgress.Transfer download = progress.getDownload();
+        assertEquals(2048, download.getBytes());
+        assertEquals(-1, download.getTotalBytes());
+        assertTrue(download.isComplete());
+        assertThat(fractions.isEmpty(), is(false));
+        assertThat(fractions.stream().allMatch(f -> f == -1.0D), is(true));
+        // No content, the upload is complete as soon as the request is sent.
+        assertThat(progress.getUpload().getBytes(), is(0L));
+        assertTrue(progress.getUpload().isComplete());
+    }
+}
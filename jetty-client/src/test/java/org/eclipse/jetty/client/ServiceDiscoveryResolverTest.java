@@ -0,0 +1,214 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.net.ServerSocket;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Set;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class ServiceDiscoveryResolverTest
+{
+    private static final String SERVICE = "service.test";
+
+    private final AtomicReference<List<InetSocketAddress>> addresses = new AtomicReference<>();
+    private final AtomicReference<CountDownLatch> handlerLatch = new AtomicReference<>();
+    private Server server;
+    private ServerConnector connector1;
+    private ServerConnector connector2;
+    private HttpClient client;
+    private ServiceDiscoveryResolver resolver;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector1 = new ServerConnector(server, 1, 1);
+        server.addConnector(connector1);
+        connector2 = new ServerConnector(server, 1, 1);
+        server.addConnector(connector2);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                CountDownLatch latch = handlerLatch.getAndSet(null);
+                if (latch != null)
+                {
+                    try
+                    {
+                        latch.await(5, TimeUnit.SECONDS);
+                    }
+                    catch (InterruptedException x)
+                    {
+                        throw new IOException(x);
+                    }
+                }
+                response.getWriter().print(request.getLocalPort());
+            }
+        });
+        server.start();
+
+        client = new HttpClient();
+        resolver = new ServiceDiscoveryResolver(client, new SocketAddressResolver()
+        {
+            @Override
+            public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+            {
+                promise.succeeded(addresses.get());
+            }
+        });
+        resolver.addService(SERVICE);
+        client.setSocketAddressResolver(resolver);
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (server != null)
+            server.stop();
+    }
+
+    private InetSocketAddress address(ServerConnector connector)
+    {
+        return new InetSocketAddress("127.0.0.1", connector.getLocalPort());
+    }
+
+    private Request newRequest()
+    {
+        return client.newRequest(SERVICE, 8080).timeout(5, TimeUnit.SECONDS);
+    }
+
+    private int send(Request request) throws Exception
+    {
+        ContentResponse response = request.send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        return Integer.parseInt(response.getContentAsString());
+    }
+
+    @Test
+    public void testConnectionsSpreadAcrossAddresses() throws Exception
+    {
+        addresses.set(List.of(address(connector1), address(connector2)));
+
+        Set<Integer> ports = new HashSet<>();
+        for (int i = 0; i < 32; ++i)
+        {
+            ports.add(send(newRequest().headers(headers -> headers.put(HttpHeader.CONNECTION, "close"))));
+        }
+
+        assertThat(ports, is(Set.of(connector1.getLocalPort(), connector2.getLocalPort())));
+        assertThat(resolver.getAddresses(SERVICE, 8080), contains(address(connector1), address(connector2)));
+    }
+
+    @Test
+    public void testIdleConnectionToRemovedAddressIsDrained() throws Exception
+    {
+        addresses.set(List.of(address(connector1)));
+        assertThat(send(newRequest()), is(connector1.getLocalPort()));
+
+        HttpDestination destination = (HttpDestination)client.resolveDestination(newRequest());
+        AbstractConnectionPool connectionPool = (AbstractConnectionPool)destination.getConnectionPool();
+        assertThat(connectionPool.getIdleConnectionCount(), is(1));
+
+        addresses.set(List.of(address(connector2)));
+        resolver.refresh();
+
+        await().atMost(5, TimeUnit.SECONDS).until(connectionPool::getConnectionCount, is(0));
+        assertThat(resolver.getDrainedConnections(), is(1L));
+        assertThat(resolver.getAddresses(SERVICE, 8080), contains(address(connector2)));
+        assertThat(send(newRequest()), is(connector2.getLocalPort()));
+    }
+
+    @Test
+    public void testActiveConnectionToRemovedAddressIsDrainedWhenReleased() throws Exception
+    {
+        addresses.set(List.of(address(connector1)));
+        CountDownLatch latch = new CountDownLatch(1);
+        handlerLatch.set(latch);
+        CountDownLatch responseLatch = new CountDownLatch(1);
+        newRequest().send(result ->
+        {
+            if (result.isSucceeded() && result.getResponse().getStatus() == HttpStatus.OK_200)
+                responseLatch.countDown();
+        });
+
+        HttpDestination destination = (HttpDestination)client.resolveDestination(newRequest());
+        AbstractConnectionPool connectionPool = (AbstractConnectionPool)destination.getConnectionPool();
+        await().atMost(5, TimeUnit.SECONDS).until(connectionPool::getActiveConnectionCount, is(1));
+
+        addresses.set(List.of(address(connector2)));
+        resolver.refresh();
+        await().atMost(5, TimeUnit.SECONDS).until(resolver::getDrainedConnections, is(1L));
+
+        // The exchange in progress completes normally.
+        latch.countDown();
+        assertTrue(responseLatch.await(5, TimeUnit.SECONDS));
+
+        // The drained connection is closed when released.
+        await().atMost(5, TimeUnit.SECONDS).until(connectionPool::getConnectionCount, is(0));
+        assertThat(send(newRequest()), is(connector2.getLocalPort()));
+    }
+
+    @Test
+    public void testFailedAddressIsAvoided() throws Exception
+    {
+        InetSocketAddress unreachable;
+        try (ServerSocket serverSocket = new ServerSocket(0))
+        {
+            unreachable = new InetSocketAddress("127.0.0.1", serverSocket.getLocalPort());
+        }
+        addresses.set(List.of(unreachable, address(connector1)));
+        resolver.setFailureBackoff(60000);
+
+        for (int i = 0; i < 16; ++i)
+        {
+            assertThat(send(newRequest().headers(headers -> headers.put(HttpHeader.CONNECTION, "close"))), is(connector1.getLocalPort()));
+        }
+
+        // Once failed, the unreachable address is not tried first anymore.
+        assertThat(resolver.getConnectFailures(), lessThanOrEqualTo(1L));
+    }
+}
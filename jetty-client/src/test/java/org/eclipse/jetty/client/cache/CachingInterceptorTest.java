@@ -0,0 +1,348 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.cache;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Path;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractHttpClientServerTest;
+import org.eclipse.jetty.client.EmptyServerHandler;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class CachingInterceptorTest extends AbstractHttpClientServerTest
+{
+    public WorkDir workDir;
+    private final AtomicInteger serverRequests = new AtomicInteger();
+    private CachingInterceptor cache;
+
+    private void startCache(Scenario scenario, EmptyServerHandler handler) throws Exception
+    {
+        start(scenario, handler);
+        cache = new CachingInterceptor(client, new HeapCacheStorage());
+        client.addInterceptor(cache);
+    }
+
+    private ContentResponse send(Scenario scenario, String path) throws Exception
+    {
+        return client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path(path)
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testFreshResponseServedFromCache(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+                response.getWriter().print("content" + serverRequests.incrementAndGet());
+            }
+        });
+
+        ContentResponse response = send(scenario, "/fresh");
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content1", response.getContentAsString());
+
+        response = send(scenario, "/fresh");
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content1", response.getContentAsString());
+        assertNotNull(response.getHeaders().get(HttpHeader.AGE));
+
+        // A different URI is not served from the cache.
+        response = send(scenario, "/fresh?q=1");
+        assertEquals("content2", response.getContentAsString());
+
+        assertEquals(2, serverRequests.get());
+        assertEquals(1, cache.getHits());
+        assertEquals(2, cache.getMisses());
+        assertEquals(2, cache.getStores());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRevalidationWithETag(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-cache");
+                response.setHeader(HttpHeader.ETAG.asString(), "\"v1\"");
+                if ("\"v1\"".equals(request.getHeader(HttpHeader.IF_NONE_MATCH.asString())))
+                    response.setStatus(HttpStatus.NOT_MODIFIED_304);
+                else
+                    response.getWriter().print("content");
+            }
+        });
+
+        ContentResponse response = send(scenario, "/etag");
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content", response.getContentAsString());
+
+        response = send(scenario, "/etag");
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content", response.getContentAsString());
+        assertEquals("\"v1\"", response.getHeaders().get(HttpHeader.ETAG));
+
+        assertEquals(2, serverRequests.get());
+        assertEquals(0, cache.getHits());
+        assertEquals(1, cache.getRevalidations());
+        assertEquals(1, cache.getValidations());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRevalidationWithNewContent(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                int count = serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=0");
+                response.setHeader(HttpHeader.ETAG.asString(), "\"v" + count + "\"");
+                response.getWriter().print("content" + count);
+            }
+        });
+
+        assertEquals("content1", send(scenario, "/changed").getContentAsString());
+        assertEquals("content2", send(scenario, "/changed").getContentAsString());
+        assertEquals("content3", send(scenario, "/changed").getContentAsString());
+
+        assertEquals(2, cache.getRevalidations());
+        assertEquals(0, cache.getValidations());
+        assertEquals(3, cache.getStores());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testVary(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+                response.setHeader(HttpHeader.VARY.asString(), HttpHeader.ACCEPT_LANGUAGE.asString());
+                response.getWriter().print(request.getHeader(HttpHeader.ACCEPT_LANGUAGE.asString()));
+            }
+        });
+
+        for (String language : new String[]{"en", "fr", "en", "fr"})
+        {
+            ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+                .scheme(scenario.getScheme())
+                .path("/vary")
+                .headers(headers -> headers.put(HttpHeader.ACCEPT_LANGUAGE, language))
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(language, response.getContentAsString());
+        }
+
+        // Only one variant per URI is stored.
+        assertEquals(4, serverRequests.get());
+        assertEquals(0, cache.getHits());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testNoStore(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60, no-store");
+                response.getWriter().print("content");
+            }
+        });
+
+        send(scenario, "/nostore");
+        send(scenario, "/nostore");
+
+        assertEquals(2, serverRequests.get());
+        assertEquals(0, cache.getStores());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testUnsafeRequestInvalidates(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+                response.getWriter().print(request.getMethod());
+            }
+        });
+
+        assertEquals("GET", send(scenario, "/resource").getContentAsString());
+        assertEquals("GET", send(scenario, "/resource").getContentAsString());
+        assertEquals(1, serverRequests.get());
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .path("/resource")
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals("POST", response.getContentAsString());
+        assertEquals(1, cache.getInvalidations());
+
+        assertEquals("GET", send(scenario, "/resource").getContentAsString());
+        assertEquals(3, serverRequests.get());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRequestDirectives(Scenario scenario) throws Exception
+    {
+        startCache(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                serverRequests.incrementAndGet();
+                response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=60");
+                response.getWriter().print("content");
+            }
+        });
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/directives")
+            .headers(headers -> headers.put(HttpHeader.CACHE_CONTROL, "only-if-cached"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.GATEWAY_TIMEOUT_504, response.getStatus());
+        assertEquals(0, serverRequests.get());
+
+        send(scenario, "/directives");
+        assertEquals(1, serverRequests.get());
+
+        // The request no-cache directive forces the request to be sent.
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/directives")
+            .headers(headers -> headers.put(HttpHeader.CACHE_CONTROL, "no-cache"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(2, serverRequests.get());
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/directives")
+            .headers(headers -> headers.put(HttpHeader.CACHE_CONTROL, "only-if-cached"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("content", response.getContentAsString());
+        assertEquals(2, serverRequests.get());
+    }
+
+    @Test
+    public void testFileCacheStorage() throws Exception
+    {
+        Path directory = workDir.getEmptyPathDir();
+        FileCacheStorage storage = new FileCacheStorage(directory);
+        String key = "http://localhost:8080/path?query";
+        assertNull(storage.get(key));
+
+        HttpFields headers = HttpFields.build()
+            .put(HttpHeader.CONTENT_TYPE, "text/plain")
+            .put(HttpHeader.ETAG, "\"v1\"");
+        byte[] content = "content".getBytes(StandardCharsets.UTF_8);
+        storage.put(key, new CachedResponse(HttpStatus.OK_200, headers, content, Map.of("accept-language", "en"), 1000, 2000));
+
+        // Responses survive across storage instances.
+        CachedResponse cached = new FileCacheStorage(directory).get(key);
+        assertNotNull(cached);
+        assertEquals(HttpStatus.OK_200, cached.getStatus());
+        assertEquals("text/plain", cached.getHeaders().get(HttpHeader.CONTENT_TYPE));
+        assertEquals("\"v1\"", cached.getHeaders().get(HttpHeader.ETAG));
+        assertArrayEquals(content, cached.getContent());
+        assertEquals(1000, cached.getRequestTime());
+        assertEquals(2000, cached.getResponseTime());
+        assertTrue(cached.matches(HttpFields.build().put(HttpHeader.ACCEPT_LANGUAGE, "en")));
+        assertFalse(cached.matches(HttpFields.build().put(HttpHeader.ACCEPT_LANGUAGE, "fr")));
+
+        storage.remove(key);
+        assertNull(storage.get(key));
+
+        storage.put(key, cached);
+        storage.clear();
+        assertNull(storage.get(key));
+    }
+
+    @Test
+    public void testHeapCacheStorageEviction()
+    {
+        HeapCacheStorage storage = new HeapCacheStorage(2, 10);
+        CachedResponse response = new CachedResponse(HttpStatus.OK_200, HttpFields.EMPTY, new byte[4], Map.of(), 0, 0);
+        storage.put("a", response);
+        storage.put("b", response);
+        // Access "a" so that "b" is the least recently used.
+        assertNotNull(storage.get("a"));
+        storage.put("c", response);
+        assertNull(storage.get("b"));
+        assertEquals(2, storage.getSize());
+
+        // Exceeding the max content bytes evicts entries.
+        storage.put("d", new CachedResponse(HttpStatus.OK_200, HttpFields.EMPTY, new byte[8], Map.of(), 0, 0));
+        assertEquals(1, storage.getSize());
+        assertNotNull(storage.get("d"));
+        assertEquals(8, storage.getContentBytes());
+    }
+}
@@ -0,0 +1,205 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractHttpClientServerTest;
+import org.eclipse.jetty.client.EmptyServerHandler;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.toolchain.test.MavenTestingUtils;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.hasEntry;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class HarRecorderTest extends AbstractHttpClientServerTest
+{
+    private HarRecorder har;
+
+    private void start(Scenario scenario) throws Exception
+    {
+        startServer(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                String content = IO.toString(request.getInputStream());
+                response.setContentType("text/plain");
+                response.addHeader("Set-Cookie", "session=abc; Path=/");
+                if (target.startsWith("/binary"))
+                {
+                    response.setContentType("application/octet-stream");
+                    response.getOutputStream().write(new byte[]{0, 1, 2, 3});
+                }
+                else
+                {
+                    response.getWriter().print("echo:" + content);
+                }
+            }
+        });
+        startClient(scenario, httpClient ->
+        {
+            har = new HarRecorder(httpClient);
+            httpClient.addBean(har);
+        });
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> map(Map<String, Object> map, String key)
+    {
+        return (Map<String, Object>)map.get(key);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testExchangeRecorded(Scenario scenario) throws Exception
+    {
+        start(scenario);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .path("/echo")
+            .param("q", "1")
+            .body(new StringRequestContent("text/plain", "hello"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+
+        List<Map<String, Object>> entries = har.getEntries();
+        assertEquals(1, entries.size());
+        Map<String, Object> entry = entries.get(0);
+
+        Map<String, Object> request = map(entry, "request");
+        assertThat(request, hasEntry("method", "POST"));
+        assertThat((String)request.get("url"), containsString("/echo?q=1"));
+        assertThat(request.get("queryString").toString(), containsString("q"));
+        assertThat(map(request, "postData"), hasEntry("text", "hello"));
+
+        Map<String, Object> harResponse = map(entry, "response");
+        assertThat(harResponse, hasEntry("status", 200));
+        assertThat(map(harResponse, "content"), hasEntry("text", "echo:hello"));
+        assertThat(harResponse.get("cookies").toString(), containsString("session"));
+
+        // The first exchange over a new connection records the connection setup.
+        Map<String, Object> timings = map(entry, "timings");
+        assertThat((Double)timings.get("connect"), greaterThanOrEqualTo(0D));
+        if (scenario.getScheme().equals("https"))
+            assertThat((Double)timings.get("ssl"), greaterThanOrEqualTo(0D));
+        else
+            assertThat(timings.get("ssl"), is(-1D));
+        assertThat((Double)timings.get("wait"), greaterThanOrEqualTo(0D));
+
+        // The second exchange reuses the connection.
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        entries = har.getEntries();
+        assertEquals(2, entries.size());
+        assertThat(map(entries.get(1), "timings").get("connect"), is(-1D));
+        assertEquals(entry.get("connection"), entries.get(1).get("connection"));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testContentTruncatedAndBinaryEncoded(Scenario scenario) throws Exception
+    {
+        start(scenario);
+        har.setMaxContentLength(3);
+
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/binary")
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        Map<String, Object> content = map(map(har.getEntries().get(0), "response"), "content");
+        assertThat(content, hasEntry("size", 4L));
+        assertThat(content, hasEntry("encoding", "base64"));
+        assertThat(content, hasEntry("text", "AAEC"));
+        assertThat((String)content.get("comment"), containsString("truncated"));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRecordingToggleAndMaxEntries(Scenario scenario) throws Exception
+    {
+        start(scenario);
+        har.setMaxEntries(2);
+
+        for (int i = 0; i < 3; ++i)
+        {
+            client.newRequest("localhost", connector.getLocalPort())
+                .scheme(scenario.getScheme())
+                .path("/" + i)
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+        }
+        List<Map<String, Object>> entries = har.getEntries();
+        assertEquals(2, entries.size());
+        assertThat((String)map(entries.get(0), "request").get("url"), containsString("/1"));
+
+        har.setRecording(false);
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(2, har.getEntryCount());
+
+        har.clear();
+        assertEquals(0, har.getEntryCount());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testWriteTo(Scenario scenario) throws Exception
+    {
+        start(scenario);
+
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        Path file = MavenTestingUtils.getTargetTestingPath(HarRecorderTest.class.getSimpleName()).resolve(scenario.getScheme() + ".har");
+        Files.createDirectories(file.getParent());
+        har.writeTo(file);
+
+        @SuppressWarnings("unchecked")
+        Map<String, Object> json = (Map<String, Object>)new JSON().fromJSON(Files.readString(file, StandardCharsets.UTF_8));
+        Map<String, Object> log = map(json, "log");
+        assertThat(log, hasEntry("version", "1.2"));
+        assertEquals(1, ((Object[])log.get("entries")).length);
+    }
+}
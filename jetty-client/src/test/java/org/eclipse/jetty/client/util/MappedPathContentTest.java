@@ -0,0 +1,243 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.Random;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class MappedPathContentTest
+{
+    public WorkDir workDir;
+    private Server server;
+    private ServerConnector connector;
+    private HttpClient client;
+
+    private void start(Handler handler) throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+        server.setHandler(handler);
+        server.start();
+
+        client = new HttpClient();
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (server != null)
+            server.stop();
+    }
+
+    private static byte[] randomBytes(int size)
+    {
+        byte[] bytes = new byte[size];
+        new Random().nextBytes(bytes);
+        return bytes;
+    }
+
+    @Test
+    public void testChunksSpanMappedWindows() throws Exception
+    {
+        byte[] bytes = randomBytes(10_000);
+        Path file = workDir.getEmptyPathDir().resolve("upload.bin");
+        Files.write(file, bytes);
+        MappedPathRequestContent content = new MappedPathRequestContent("application/octet-stream", file, 300, 1024);
+
+        ByteBuffer received = ByteBuffer.allocate(bytes.length);
+        AtomicBoolean last = new AtomicBoolean();
+        AtomicInteger chunks = new AtomicInteger();
+        AtomicReference<Request.Content.Subscription> subscriptionRef = new AtomicReference<>();
+        Request.Content.Subscription subscription = content.subscribe((buffer, isLast, callback) ->
+        {
+            assertThat(buffer.remaining(), lessThanOrEqualTo(300));
+            chunks.incrementAndGet();
+            received.put(buffer);
+            callback.succeeded();
+            last.set(isLast);
+            if (!isLast)
+                subscriptionRef.get().demand();
+        }, true);
+        subscriptionRef.set(subscription);
+        subscription.demand();
+
+        assertTrue(last.get());
+        assertArrayEquals(bytes, received.array());
+        // Each window of 1024 bytes is emitted as chunks of 300, 300, 300 and 124 bytes.
+        assertThat(chunks.get(), is(39));
+    }
+
+    @Test
+    public void testUploadAndDownload() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                // Echo the request content back.
+                try (InputStream input = request.getInputStream(); OutputStream output = response.getOutputStream())
+                {
+                    IO.copy(input, output);
+                }
+            }
+        });
+
+        byte[] bytes = randomBytes(1024 * 1024 + 17);
+        Path dir = workDir.getEmptyPathDir();
+        Path upload = dir.resolve("upload.bin");
+        Files.write(upload, bytes);
+        Path download = dir.resolve("download.bin");
+
+        PathResponseListener listener = new PathResponseListener(download);
+        client.newRequest("localhost", connector.getLocalPort())
+            .method(HttpMethod.POST)
+            .body(new MappedPathRequestContent("application/octet-stream", upload, 16 * 1024, 256 * 1024))
+            .timeout(15, TimeUnit.SECONDS)
+            .send(listener);
+
+        assertThat(listener.get(15, TimeUnit.SECONDS), is(download));
+        assertThat(listener.getBytesWritten(), is((long)bytes.length));
+        assertArrayEquals(bytes, Files.readAllBytes(download));
+    }
+
+    @Test
+    public void testFailedDownloadDeletesFile() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                response.setContentLength(1024);
+                response.getOutputStream().write(new byte[512]);
+                response.flushBuffer();
+                // Close the connection before all the content is sent.
+                jettyRequest.getHttpChannel().getEndPoint().close();
+            }
+        });
+
+        Path download = workDir.getEmptyPathDir().resolve("download.bin");
+        PathResponseListener listener = new PathResponseListener(download);
+        client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+
+        assertThrows(ExecutionException.class, () -> listener.get(5, TimeUnit.SECONDS));
+        assertFalse(Files.exists(download));
+    }
+
+    @Test
+    public void testNoOverwrite() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                response.getOutputStream().write(new byte[16]);
+            }
+        });
+
+        Path download = workDir.getEmptyPathDir().resolve("download.bin");
+        Files.write(download, new byte[]{1, 2, 3});
+        PathResponseListener listener = new PathResponseListener(download, false);
+        client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+
+        assertThrows(ExecutionException.class, () -> listener.get(5, TimeUnit.SECONDS));
+        // The existing file is not overwritten nor deleted.
+        assertArrayEquals(new byte[]{1, 2, 3}, Files.readAllBytes(download));
+    }
+
+    @Test
+    public void testDownloadToDirectory() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                if (target.startsWith("/attachment"))
+                    response.setHeader("Content-Disposition", "attachment; filename=\"../rates.txt\"; filename*=UTF-8''..%2F%E2%82%AC%20rates.txt");
+                response.getOutputStream().write(new byte[16]);
+            }
+        });
+
+        Path dir = workDir.getEmptyPathDir();
+
+        PathResponseListener listener = new PathResponseListener(dir);
+        client.newRequest("localhost", connector.getLocalPort())
+            .path("/attachment")
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+        // The file name is decoded and stripped of the path.
+        Path download = dir.resolve("\u20AC rates.txt");
+        assertThat(listener.get(5, TimeUnit.SECONDS), is(download));
+        assertThat(listener.getPath(), is(download));
+        assertThat(Files.size(download), is(16L));
+
+        listener = new PathResponseListener(dir);
+        client.newRequest("localhost", connector.getLocalPort())
+            .path("/files/report%20v2.pdf")
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+        // Without Content-Disposition, the file name is the last segment of the URI path.
+        assertThat(listener.get(5, TimeUnit.SECONDS), is(dir.resolve("report v2.pdf")));
+    }
+}
@@ -25,11 +25,13 @@ import java.nio.file.Files;
 import java.nio.file.Path;
 import java.nio.file.StandardOpenOption;
 import java.util.ArrayList;
+import java.util.Base64;
 import java.util.Collection;
 import java.util.List;
 import java.util.Random;
 import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicBoolean;
 import javax.servlet.MultipartConfigElement;
 import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
@@ -51,6 +53,7 @@ import org.junit.jupiter.params.provider.ArgumentsSource;
 import static org.eclipse.jetty.toolchain.test.StackUtils.supply;
 import static org.junit.jupiter.api.Assertions.assertArrayEquals;
 import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
 import static org.junit.jupiter.api.Assertions.assertNotNull;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
@@ -432,6 +435,55 @@ public class MultiPartContentTest extends AbstractHttpClientServerTest
         assertTrue(responseLatch.await(5, TimeUnit.SECONDS));
     }
 
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testLazyFilePartWithBase64TransferEncoding(Scenario scenario) throws Exception
+    {
+        String name = "file";
+        String fileName = "upload.bin";
+        byte[] data = new byte[1024];
+        new Random().nextBytes(data);
+        start(scenario, new AbstractMultiPartHandler()
+        {
+            @Override
+            protected void handle(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+            {
+                // The length of a lazy part is unknown.
+                assertEquals(-1, request.getContentLengthLong());
+                Collection<Part> parts = request.getParts();
+                assertEquals(1, parts.size());
+                Part part = parts.iterator().next();
+                assertEquals(name, part.getName());
+                assertEquals(fileName, part.getSubmittedFileName());
+                assertEquals("base64", part.getHeader("Content-Transfer-Encoding"));
+                String encoded = IO.toString(part.getInputStream(), StandardCharsets.US_ASCII);
+                assertArrayEquals(data, Base64.getMimeDecoder().decode(encoded));
+            }
+        });
+
+        AtomicBoolean opened = new AtomicBoolean();
+        MultiPartRequestContent multiPart = new MultiPartRequestContent();
+        HttpFields.Mutable fields = HttpFields.build();
+        fields.put(HttpHeader.CONTENT_TYPE, "application/octet-stream");
+        fields.put("Content-Transfer-Encoding", "base64");
+        multiPart.addFilePart(name, fileName, () ->
+        {
+            opened.set(true);
+            return new InputStreamRequestContent(new ByteArrayInputStream(data), 100);
+        }, fields);
+        multiPart.close();
+        assertFalse(opened.get());
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .method(HttpMethod.POST)
+            .body(multiPart)
+            .send();
+
+        assertEquals(200, response.getStatus());
+        assertTrue(opened.get());
+    }
+
     private abstract static class AbstractMultiPartHandler extends AbstractHandler
     {
         @Override
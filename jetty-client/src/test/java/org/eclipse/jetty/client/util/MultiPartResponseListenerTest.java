@@ -0,0 +1,233 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractHttpClientServerTest;
+import org.eclipse.jetty.client.EmptyServerHandler;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class MultiPartResponseListenerTest extends AbstractHttpClientServerTest
+{
+    private void startMultiPart(Scenario scenario, String contentType, String body, int chunkSize) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.setContentType(contentType);
+                OutputStream output = response.getOutputStream();
+                byte[] bytes = body.getBytes(StandardCharsets.UTF_8);
+                // Write in small chunks to split the boundaries across network reads.
+                for (int i = 0; i < bytes.length; i += chunkSize)
+                {
+                    output.write(bytes, i, Math.min(chunkSize, bytes.length - i));
+                    output.flush();
+                }
+            }
+        });
+    }
+
+    private CollectingListener send(Scenario scenario) throws Exception
+    {
+        CollectingListener listener = new CollectingListener();
+        client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send(new MultiPartResponseListener(listener));
+        assertTrue(listener.latch.await(5, TimeUnit.SECONDS));
+        return listener;
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testByteRanges(Scenario scenario) throws Exception
+    {
+        String body = "" +
+            "--RANGE\r\n" +
+            "Content-Type: text/plain\r\n" +
+            "Content-Range: bytes 0-4/20\r\n" +
+            "\r\n" +
+            "01234\r\n" +
+            "--RANGE\r\n" +
+            "Content-Type: text/plain\r\n" +
+            "Content-Range: bytes 10-19/20\r\n" +
+            "\r\n" +
+            "ABCDE\r\n-RAN\r\n" +
+            "--RANGE--\r\n";
+        startMultiPart(scenario, "multipart/byteranges; boundary=RANGE", body, 3);
+
+        CollectingListener listener = send(scenario);
+
+        assertThat(listener.failure.get(), nullValue());
+        List<MultiPartResponseListener.Part> parts = new ArrayList<>(listener.contents.keySet());
+        assertThat(parts.size(), is(2));
+        assertThat(parts.get(0).getRangeFirst(), is(0L));
+        assertThat(parts.get(0).getRangeLast(), is(4L));
+        assertThat(parts.get(0).getCompleteLength(), is(20L));
+        assertThat(listener.content(parts.get(0)), is("01234"));
+        assertThat(parts.get(1).getRangeFirst(), is(10L));
+        assertThat(parts.get(1).getContentType(), is("text/plain"));
+        assertThat(listener.content(parts.get(1)), is("ABCDE\r\n-RAN"));
+        assertThat(listener.ended, is(parts));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testFormDataWithNestedMultiPart(Scenario scenario) throws Exception
+    {
+        String body = "" +
+            "preamble\r\n" +
+            "--OUTER\r\n" +
+            "Content-Disposition: form-data; name=\"field\"\r\n" +
+            "\r\n" +
+            "value\r\n" +
+            "--OUTER\r\n" +
+            "Content-Disposition: form-data; name=\"files\"\r\n" +
+            "Content-Type: multipart/mixed; boundary=\"INNER\"\r\n" +
+            "\r\n" +
+            "--INNER\r\n" +
+            "Content-Disposition: file; filename=\"a.txt\"\r\n" +
+            "\r\n" +
+            "aaa\r\n" +
+            "--INNER\r\n" +
+            "Content-Disposition: file; filename=\"b.txt\"\r\n" +
+            "\r\n" +
+            "bbb\r\n" +
+            "--INNER--\r\n" +
+            "--OUTER--\r\n" +
+            "epilogue";
+        startMultiPart(scenario, "multipart/form-data; boundary=OUTER", body, 7);
+
+        CollectingListener listener = send(scenario);
+
+        assertThat(listener.failure.get(), nullValue());
+        List<MultiPartResponseListener.Part> parts = new ArrayList<>(listener.contents.keySet());
+        assertThat(parts.size(), is(4));
+        MultiPartResponseListener.Part field = parts.get(0);
+        assertThat(field.getName(), is("field"));
+        assertThat(listener.content(field), is("value"));
+        MultiPartResponseListener.Part files = parts.get(1);
+        assertThat(files.getName(), is("files"));
+        // The content of the enclosing part is notified as nested parts.
+        assertThat(listener.content(files), is(""));
+        assertThat(parts.get(2).getFileName(), is("a.txt"));
+        assertThat(parts.get(2).getParent(), sameInstance(files));
+        assertThat(listener.content(parts.get(2)), is("aaa"));
+        assertThat(parts.get(3).getFileName(), is("b.txt"));
+        assertThat(listener.content(parts.get(3)), is("bbb"));
+        // The enclosing part ends after its nested parts.
+        assertThat(listener.ended, is(List.of(field, parts.get(2), parts.get(3), files)));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testTruncatedMultiPart(Scenario scenario) throws Exception
+    {
+        String body = "" +
+            "--RANGE\r\n" +
+            "Content-Range: bytes 0-4/20\r\n" +
+            "\r\n" +
+            "012";
+        startMultiPart(scenario, "multipart/byteranges; boundary=RANGE", body, 1024);
+
+        CollectingListener listener = send(scenario);
+
+        assertThat(listener.failure.get(), instanceOf(IOException.class));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testNotMultiPart(Scenario scenario) throws Exception
+    {
+        startMultiPart(scenario, "text/plain", "hello", 1024);
+
+        CollectingListener listener = send(scenario);
+
+        assertThat(listener.failure.get(), instanceOf(IllegalStateException.class));
+        assertTrue(listener.contents.isEmpty());
+    }
+
+    private static class CollectingListener implements MultiPartResponseListener.Listener
+    {
+        private final Map<MultiPartResponseListener.Part, ByteArrayOutputStream> contents = new LinkedHashMap<>();
+        private final List<MultiPartResponseListener.Part> ended = new ArrayList<>();
+        private final AtomicReference<Throwable> failure = new AtomicReference<>();
+        private final CountDownLatch latch = new CountDownLatch(1);
+
+        @Override
+        public void onPart(MultiPartResponseListener.Part part)
+        {
+            contents.put(part, new ByteArrayOutputStream());
+        }
+
+        @Override
+        public void onPartContent(MultiPartResponseListener.Part part, ByteBuffer content, Callback callback)
+        {
+            contents.get(part).writeBytes(BufferUtil.toArray(content));
+            callback.succeeded();
+        }
+
+        @Override
+        public void onPartEnd(MultiPartResponseListener.Part part)
+        {
+            ended.add(part);
+        }
+
+        @Override
+        public void onSuccess(Response response)
+        {
+            latch.countDown();
+        }
+
+        @Override
+        public void onFailure(Response response, Throwable failure)
+        {
+            this.failure.set(failure);
+            latch.countDown();
+        }
+
+        private String content(MultiPartResponseListener.Part part)
+        {
+            return contents.get(part).toString(StandardCharsets.UTF_8);
+        }
+    }
+}
@@ -0,0 +1,249 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.time.Duration;
+import java.util.Base64;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractHttpClientServerTest;
+import org.eclipse.jetty.client.EmptyServerHandler;
+import org.eclipse.jetty.client.HttpResponseException;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNotEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertSame;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class OAuth2AuthenticationTest extends AbstractHttpClientServerTest
+{
+    private static final String CLIENT_ID = "client";
+    private static final String CLIENT_SECRET = "secret";
+
+    private final Set<String> accessTokens = ConcurrentHashMap.newKeySet();
+    private final Map<String, String> refreshTokens = new ConcurrentHashMap<>();
+    private final AtomicInteger tokenIds = new AtomicInteger();
+    private final AtomicInteger clientCredentialsGrants = new AtomicInteger();
+    private final AtomicInteger refreshTokenGrants = new AtomicInteger();
+    private final AtomicInteger challenges = new AtomicInteger();
+    private volatile long expiresIn = 3600;
+
+    private void startOAuth2(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                if (target.equals("/token"))
+                    token(request, response);
+                else
+                    api(request, response);
+            }
+        });
+    }
+
+    private void token(HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        String credentials = Base64.getEncoder().encodeToString((CLIENT_ID + ":" + CLIENT_SECRET).getBytes(StandardCharsets.ISO_8859_1));
+        if (!("Basic " + credentials).equals(request.getHeader(HttpHeader.AUTHORIZATION.asString())))
+        {
+            response.setStatus(HttpStatus.BAD_REQUEST_400);
+            response.setContentType("application/json");
+            response.getWriter().print("{\"error\":\"invalid_client\"}");
+            return;
+        }
+
+        String grantType = request.getParameter("grant_type");
+        if ("client_credentials".equals(grantType))
+        {
+            clientCredentialsGrants.incrementAndGet();
+        }
+        else if ("refresh_token".equals(grantType) && refreshTokens.remove(request.getParameter("refresh_token")) != null)
+        {
+            refreshTokenGrants.incrementAndGet();
+        }
+        else
+        {
+            response.setStatus(HttpStatus.BAD_REQUEST_400);
+            response.setContentType("application/json");
+            response.getWriter().print("{\"error\":\"invalid_grant\"}");
+            return;
+        }
+
+        int id = tokenIds.incrementAndGet();
+        String accessToken = "access-" + id;
+        String refreshToken = "refresh-" + id;
+        accessTokens.add(accessToken);
+        refreshTokens.put(refreshToken, accessToken);
+        response.setContentType("application/json");
+        response.getWriter().printf("{\"access_token\":\"%s\",\"token_type\":\"Bearer\",\"expires_in\":%d,\"refresh_token\":\"%s\",\"scope\":\"%s\"}",
+            accessToken, expiresIn, refreshToken, request.getParameter("scope"));
+    }
+
+    private void api(HttpServletRequest request, HttpServletResponse response)
+    {
+        String authorization = request.getHeader(HttpHeader.AUTHORIZATION.asString());
+        if (authorization != null && authorization.startsWith("Bearer ") && accessTokens.contains(authorization.substring(7)))
+            return;
+        challenges.incrementAndGet();
+        response.setHeader(HttpHeader.WWW_AUTHENTICATE.asString(), "Bearer realm=\"api\"");
+        response.setStatus(HttpStatus.UNAUTHORIZED_401);
+    }
+
+    private OAuth2Authentication newOAuth2Authentication(Scenario scenario, String clientSecret)
+    {
+        String baseURI = scenario.getScheme() + "://localhost:" + connector.getLocalPort();
+        OAuth2Authentication.HttpTokenEndpoint tokenEndpoint = new OAuth2Authentication.HttpTokenEndpoint(client, URI.create(baseURI + "/token"), CLIENT_ID, clientSecret);
+        OAuth2Authentication authentication = new OAuth2Authentication(URI.create(baseURI + "/api"), "api", tokenEndpoint);
+        client.getAuthenticationStore().addAuthentication(authentication);
+        return authentication;
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testClientCredentials(Scenario scenario) throws Exception
+    {
+        startOAuth2(scenario);
+        OAuth2Authentication authentication = newOAuth2Authentication(scenario, CLIENT_SECRET);
+        authentication.setScope("read write");
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api/one")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(1, challenges.get());
+        assertEquals(1, clientCredentialsGrants.get());
+
+        // The token is cached and sent preemptively.
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api/two")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(1, challenges.get());
+        assertEquals(1, clientCredentialsGrants.get());
+
+        OAuth2Authentication.Token token = authentication.getToken("api");
+        assertEquals("read write", token.getScope());
+        assertEquals(Duration.ofSeconds(expiresIn), token.getExpiresIn());
+        assertSame(token, authentication.getToken("api"));
+
+        // Tokens are cached per realm.
+        assertNotEquals(token.getAccessToken(), authentication.getToken("other").getAccessToken());
+        assertEquals(2, clientCredentialsGrants.get());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testTokenRefreshedBeforeExpiration(Scenario scenario) throws Exception
+    {
+        startOAuth2(scenario);
+        expiresIn = 10;
+        OAuth2Authentication authentication = newOAuth2Authentication(scenario, CLIENT_SECRET);
+        authentication.setRefreshMargin(Duration.ofSeconds(9));
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        String accessToken = authentication.getToken("api").getAccessToken();
+
+        Thread.sleep(1500);
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        // The token was refreshed before being sent.
+        assertEquals(1, challenges.get());
+        assertEquals(1, clientCredentialsGrants.get());
+        assertEquals(1, refreshTokenGrants.get());
+        assertNotEquals(accessToken, authentication.getToken("api").getAccessToken());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRejectedTokenIsRenewed(Scenario scenario) throws Exception
+    {
+        startOAuth2(scenario);
+        newOAuth2Authentication(scenario, CLIENT_SECRET);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+
+        // Revoke the access tokens, but not the refresh tokens.
+        accessTokens.clear();
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(2, challenges.get());
+        assertEquals(1, clientCredentialsGrants.get());
+        assertEquals(1, refreshTokenGrants.get());
+
+        // Revoke all the tokens, the refresh fails and falls back to client credentials.
+        accessTokens.clear();
+        refreshTokens.clear();
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(2, clientCredentialsGrants.get());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testTokenRequestFailure(Scenario scenario) throws Exception
+    {
+        startOAuth2(scenario);
+        newOAuth2Authentication(scenario, "wrong");
+
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .path("/api")
+            .send());
+        assertThat(failure.getCause(), instanceOf(HttpResponseException.class));
+        assertEquals(0, clientCredentialsGrants.get());
+        assertNull(client.getAuthenticationStore().findAuthenticationResult(URI.create(scenario.getScheme() + "://localhost:" + connector.getLocalPort() + "/api")));
+    }
+}
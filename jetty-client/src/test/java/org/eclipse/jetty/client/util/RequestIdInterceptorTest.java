@@ -0,0 +1,94 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.client.util;
+
+import java.io.IOException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractHttpClientServerTest;
+import org.eclipse.jetty.client.EmptyServerHandler;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.server.Request;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ArgumentsSource;
+import org.slf4j.MDC;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class RequestIdInterceptorTest extends AbstractHttpClientServerTest
+{
+    private void start(Scenario scenario) throws Exception
+    {
+        start(scenario, new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getWriter().print(request.getHeader(RequestIdInterceptor.DEFAULT_HEADER));
+            }
+        });
+        client.addInterceptor(new RequestIdInterceptor());
+    }
+
+    @AfterEach
+    public void clearMDC()
+    {
+        MDC.remove(RequestIdInterceptor.DEFAULT_MDC_KEY);
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testRequestIdForwardedFromMDC(Scenario scenario) throws Exception
+    {
+        start(scenario);
+
+        MDC.put(RequestIdInterceptor.DEFAULT_MDC_KEY, "request-1");
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals("request-1", response.getContentAsString());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testExplicitHeaderNotReplaced(Scenario scenario) throws Exception
+    {
+        start(scenario);
+
+        MDC.put(RequestIdInterceptor.DEFAULT_MDC_KEY, "request-1");
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .headers(headers -> headers.put(RequestIdInterceptor.DEFAULT_HEADER, "explicit"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals("explicit", response.getContentAsString());
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(ScenarioProvider.class)
+    public void testNoCurrentRequestId(Scenario scenario) throws Exception
+    {
+        start(scenario);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .scheme(scenario.getScheme())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals("null", response.getContentAsString());
+    }
+}
@@ -28,3 +28,16 @@ etc/jetty-deploy.xml
 
 # Whether to extract *.war files
 # jetty.deploy.extractWars=true
+
+# Whether ${env.NAME} and ${sys.name} references, optionally with a
+# ${env.NAME:-default} value, are expanded in context XML files
+# jetty.deploy.expandVariables=false
+
+# Whether to fail the deployment if a referenced variable is undefined
+# jetty.deploy.strictVariables=false
+
+# Whether changed webapps are swapped without dropping in-flight requests
+# jetty.deploy.hotSwap=false
+
+# Max time (in ms) to wait for the in-flight requests of a swapped webapp
+# jetty.deploy.drainTimeout=30000
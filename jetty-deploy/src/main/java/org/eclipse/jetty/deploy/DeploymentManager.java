@@ -24,7 +24,10 @@ import java.util.Map;
 import java.util.Objects;
 import java.util.Queue;
 import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
 
+import org.eclipse.jetty.deploy.bindings.RequestDrainBinding;
 import org.eclipse.jetty.deploy.bindings.StandardDeployer;
 import org.eclipse.jetty.deploy.bindings.StandardStarter;
 import org.eclipse.jetty.deploy.bindings.StandardStopper;
@@ -33,8 +36,10 @@ import org.eclipse.jetty.deploy.graph.Edge;
 import org.eclipse.jetty.deploy.graph.Node;
 import org.eclipse.jetty.deploy.graph.Path;
 import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.server.handler.ContextHandlerCollection;
 import org.eclipse.jetty.util.AttributesMap;
+import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.MultiException;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
@@ -129,6 +134,8 @@ public class DeploymentManager extends ContainerLifeCycle
     private ContextHandlerCollection _contexts;
     private boolean _useStandardBindings = true;
     private String _defaultLifeCycleGoal = AppLifeCycle.STARTED;
+    private boolean _hotSwap;
+    private long _drainTimeout = 30000;
 
     /**
      * Receive an app for processing.
@@ -169,7 +176,10 @@ public class DeploymentManager extends ContainerLifeCycle
         for (AppProvider provider : providers)
         {
             if (_providers.add(provider))
+            {
+                provider.setDeploymentManager(this);
                 addBean(provider, true);
+            }
         }
     }
 
@@ -183,6 +193,7 @@ public class DeploymentManager extends ContainerLifeCycle
         if (isRunning())
             throw new IllegalStateException();
         _providers.add(provider);
+        provider.setDeploymentManager(this);
         addBean(provider, true);
     }
 
@@ -238,6 +249,7 @@ public class DeploymentManager extends ContainerLifeCycle
             addLifeCycleBinding(new StandardStarter());
             addLifeCycleBinding(new StandardStopper());
             addLifeCycleBinding(new StandardUndeployer());
+            addLifeCycleBinding(new RequestDrainBinding());
         }
 
         // Start all of the AppProviders
@@ -290,6 +302,16 @@ public class DeploymentManager extends ContainerLifeCycle
         return null;
     }
 
+    private AppEntry findAppEntry(App app)
+    {
+        for (AppEntry entry : _apps)
+        {
+            if (entry.app == app)
+                return entry;
+        }
+        return null;
+    }
+
     public App getAppByOriginId(String originId)
     {
         AppEntry entry = findAppByOriginId(originId);
@@ -428,7 +450,7 @@ public class DeploymentManager extends ContainerLifeCycle
             if (entry.app.equals(app))
             {
                 if (!AppLifeCycle.UNDEPLOYED.equals(entry.lifecyleNode.getName()))
-                    requestAppGoal(entry.app, AppLifeCycle.UNDEPLOYED);
+                    requestAppGoal(entry, AppLifeCycle.UNDEPLOYED);
                 it.remove();
                 LOG.debug("Deployable removed: {}", entry.app);
             }
@@ -571,6 +593,120 @@ public class DeploymentManager extends ContainerLifeCycle
         requestAppGoal(appentry, nodeName);
     }
 
+    /**
+     * <p>Replaces an app with a new version of the same app, without dropping requests.</p>
+     * <p>The new app is deployed and started while the old app keeps serving requests;
+     * then new requests are routed to the new app, the requests in flight in the old
+     * app are drained for at most the {@link #getDrainTimeout() drain timeout}, and
+     * finally the old app is undeployed and removed.</p>
+     * <p>If the new app fails to start, it is removed and the old app keeps serving requests.
+     * Requests in flight can only be drained for apps deployed while
+     * {@link #isHotSwap() hot swap} is enabled.</p>
+     *
+     * @param oldApp the app to replace
+     * @param newApp the app that replaces the old app
+     * @return true if the old app has been replaced, false if the new app failed to start
+     */
+    public boolean swapApp(App oldApp, App newApp)
+    {
+        AppEntry oldEntry = findAppEntry(oldApp);
+        if (oldEntry == null || !isRunning() || !AppLifeCycle.STARTED.equals(oldEntry.lifecyleNode.getName()))
+        {
+            // Nothing to drain, just replace the app.
+            if (oldEntry != null)
+                removeApp(oldApp);
+            addApp(newApp);
+            return true;
+        }
+
+        LOG.info("Swapping {} with {}", oldApp, newApp);
+        AppEntry newEntry = new AppEntry();
+        newEntry.app = newApp;
+        newEntry.setLifeCycleNode(_lifecycle.getNodeByName(AppLifeCycle.UNDEPLOYED));
+        _apps.add(newEntry);
+        requestAppGoal(newEntry, AppLifeCycle.STARTED);
+
+        if (!AppLifeCycle.STARTED.equals(newEntry.lifecyleNode.getName()))
+        {
+            LOG.warn("Unable to start {}, keeping {}", newApp, oldApp);
+            _apps.remove(newEntry);
+            undeployHandler(newApp.getContextHandler());
+            return false;
+        }
+
+        // Contexts with the same path are routed in the order they were added, so
+        // moving the old context after the new one routes new requests to the new
+        // context, while asynchronous dispatches still go to the old context.
+        ContextHandler oldContext = oldApp.getContextHandler();
+        _contexts.unmanage(oldContext);
+        undeployHandler(oldContext);
+        deployHandler(oldContext);
+
+        RequestDrainBinding.Drainer drainer = RequestDrainBinding.getDrainer(oldContext);
+        if (drainer != null)
+        {
+            try
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Draining {} for {}", drainer, oldApp);
+                drainer.drain().get(getDrainTimeout(), TimeUnit.MILLISECONDS);
+            }
+            catch (TimeoutException x)
+            {
+                LOG.warn("Drain timeout for {}, {} requests still in flight", oldApp, drainer.getRequests());
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Unable to drain {}", oldApp, x);
+            }
+        }
+
+        requestAppGoal(oldEntry, AppLifeCycle.UNDEPLOYED);
+        _apps.remove(oldEntry);
+        LOG.info("Swapped {} with {}", oldApp, newApp);
+        return true;
+    }
+
+    @ManagedOperation(value = "swap the app with a new instance created from the same origin, draining requests", impact = "ACTION")
+    public void swapApp(@Name("appId") String appId)
+    {
+        AppEntry entry = findAppByOriginId(appId);
+        if (entry == null)
+            throw new IllegalStateException("App not being tracked by Deployment Manager: " + appId);
+        App app = entry.app;
+        swapApp(app, new App(this, app.getAppProvider(), app.getOriginId()));
+    }
+
+    private void deployHandler(ContextHandler context)
+    {
+        try
+        {
+            Callback.Completable blocker = new Callback.Completable();
+            _contexts.deployHandler(context, blocker);
+            blocker.get();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to deploy {}", context, x);
+        }
+    }
+
+    private void undeployHandler(ContextHandler context)
+    {
+        if (context == null)
+            return;
+        try
+        {
+            Callback.Completable blocker = new Callback.Completable();
+            _contexts.undeployHandler(context, blocker);
+            blocker.get();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to undeploy {}", context, x);
+        }
+    }
+
     /**
      * Set a contextAttribute that will be set for every Context deployed by this provider.
      *
@@ -619,6 +755,31 @@ public class DeploymentManager extends ContainerLifeCycle
         }
     }
 
+    @ManagedAttribute("Whether changed apps are swapped without dropping requests")
+    public boolean isHotSwap()
+    {
+        return _hotSwap;
+    }
+
+    /**
+     * @param hotSwap whether changed apps are {@link #swapApp(App, App) swapped} without dropping requests
+     */
+    public void setHotSwap(boolean hotSwap)
+    {
+        _hotSwap = hotSwap;
+    }
+
+    @ManagedAttribute("The max time in ms to wait for the requests of a swapped app to complete")
+    public long getDrainTimeout()
+    {
+        return _drainTimeout;
+    }
+
+    public void setDrainTimeout(long drainTimeout)
+    {
+        _drainTimeout = drainTimeout;
+    }
+
     public boolean isUseStandardBindings()
     {
         return _useStandardBindings;
@@ -0,0 +1,174 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.deploy.bindings;
+
+import java.util.concurrent.CompletableFuture;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+
+import org.eclipse.jetty.deploy.App;
+import org.eclipse.jetty.deploy.AppLifeCycle;
+import org.eclipse.jetty.deploy.graph.Node;
+import org.eclipse.jetty.server.AsyncContextEvent;
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>Tracks the requests in flight in the contexts of the apps deployed
+ * while {@link org.eclipse.jetty.deploy.DeploymentManager#isHotSwap() hot swap}
+ * is enabled, so that they can be drained when the app is swapped.</p>
+ *
+ * @see org.eclipse.jetty.deploy.DeploymentManager#swapApp(App, App)
+ */
+public class RequestDrainBinding implements AppLifeCycle.Binding
+{
+    @Override
+    public String[] getBindingTargets()
+    {
+        return new String[]{"deploying"};
+    }
+
+    @Override
+    public void processBinding(Node node, App app) throws Exception
+    {
+        if (!app.getDeploymentManager().isHotSwap())
+            return;
+        ContextHandler handler = app.getContextHandler();
+        if (handler != null && handler.getBean(Drainer.class) == null)
+            handler.addEventListener(new Drainer());
+    }
+
+    /**
+     * @param handler the context handler
+     * @return the {@link Drainer} of the given context handler, or null if the context requests are not tracked
+     */
+    public static Drainer getDrainer(ContextHandler handler)
+    {
+        return handler.getBean(Drainer.class);
+    }
+
+    /**
+     * <p>Counts the requests that entered a context and have not completed yet.</p>
+     */
+    public static class Drainer implements ContextHandler.ContextScopeListener
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final String _attribute = Drainer.class.getName() + "@" + Integer.toHexString(hashCode());
+        private final AsyncListener _onCompletion = new AsyncListener()
+        {
+            @Override
+            public void onStartAsync(AsyncEvent event)
+            {
+                event.getAsyncContext().addListener(this);
+            }
+
+            @Override
+            public void onTimeout(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onError(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onComplete(AsyncEvent event)
+            {
+                complete(((AsyncContextEvent)event).getHttpChannelState().getBaseRequest());
+            }
+        };
+        private int _requests;
+        private CompletableFuture<Void> _drained;
+
+        @Override
+        public void enterScope(ContextHandler.Context context, Request request, Object reason)
+        {
+            // Count each request only once, at its first dispatch to the context.
+            if (request == null || request.getAttribute(_attribute) != null)
+                return;
+            request.setAttribute(_attribute, Boolean.TRUE);
+            try (AutoLock l = _lock.lock())
+            {
+                ++_requests;
+            }
+        }
+
+        @Override
+        public void exitScope(ContextHandler.Context context, Request request)
+        {
+            if (request == null || request.getAttribute(_attribute) == null)
+                return;
+            HttpChannelState state = request.getHttpChannelState();
+            if (!state.isInitial())
+                return;
+            if (state.isAsyncStarted())
+                state.addListener(_onCompletion);
+            else
+                complete(request);
+        }
+
+        private void complete(Request request)
+        {
+            if (request.getAttribute(_attribute) == null)
+                return;
+            request.removeAttribute(_attribute);
+            CompletableFuture<Void> drained = null;
+            try (AutoLock l = _lock.lock())
+            {
+                if (--_requests == 0)
+                {
+                    drained = _drained;
+                    _drained = null;
+                }
+            }
+            if (drained != null)
+                drained.complete(null);
+        }
+
+        /**
+         * @return the number of requests in flight
+         */
+        public int getRequests()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _requests;
+            }
+        }
+
+        /**
+         * @return a future completed when there are no more requests in flight
+         */
+        public CompletableFuture<Void> drain()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_requests == 0)
+                    return CompletableFuture.completedFuture(null);
+                if (_drained == null)
+                    _drained = new CompletableFuture<>();
+                return _drained;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[requests=%d]", getClass().getSimpleName(), hashCode(), getRequests());
+        }
+    }
+}
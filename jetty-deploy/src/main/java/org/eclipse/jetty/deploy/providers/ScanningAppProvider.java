@@ -16,6 +16,7 @@ package org.eclipse.jetty.deploy.providers;
 import java.io.File;
 import java.io.FilenameFilter;
 import java.util.ArrayList;
+import java.util.Arrays;
 import java.util.Collection;
 import java.util.Collections;
 import java.util.HashMap;
@@ -32,12 +33,14 @@ import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.annotation.ManagedOperation;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
 import org.eclipse.jetty.util.resource.Resource;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 @ManagedObject("Abstract Provider for loading webapps")
-public abstract class ScanningAppProvider extends ContainerLifeCycle implements AppProvider
+public abstract class ScanningAppProvider extends ContainerLifeCycle implements AppProvider, Validatable
 {
     private static final Logger LOG = LoggerFactory.getLogger(ScanningAppProvider.class);
 
@@ -192,6 +195,17 @@ public abstract class ScanningAppProvider extends ContainerLifeCycle implements
         if (LOG.isDebugEnabled())
             LOG.debug("changed {}", filename);
         App app = _appMap.remove(filename);
+        if (app != null && _deploymentManager.isHotSwap())
+        {
+            App newApp = ScanningAppProvider.this.createApp(filename);
+            if (newApp != null)
+            {
+                // Keep the old app if the new app fails to start.
+                boolean swapped = _deploymentManager.swapApp(app, newApp);
+                _appMap.put(filename, swapped ? newApp : app);
+                return;
+            }
+        }
         if (app != null)
         {
             _deploymentManager.removeApp(app);
@@ -250,6 +264,66 @@ public abstract class ScanningAppProvider extends ContainerLifeCycle implements
         _deploymentManager = deploymentManager;
     }
 
+    /**
+     * <p>Checks that the monitored directories exist, and creates without
+     * starting them the contexts of the apps found in the monitored directories,
+     * so that their context descriptors are parsed and applied.</p>
+     *
+     * @param report the report to add the problems to
+     */
+    @Override
+    public void validate(ValidationReport report)
+    {
+        if (_deploymentManager == null)
+        {
+            report.addProblem(this, "No DeploymentManager");
+            return;
+        }
+        if (_monitored.isEmpty())
+        {
+            report.addProblem(this, "No configuration dir specified");
+            return;
+        }
+
+        for (Resource resource : _monitored)
+        {
+            File dir;
+            try
+            {
+                dir = resource.exists() ? resource.getFile() : null;
+            }
+            catch (Throwable x)
+            {
+                dir = null;
+            }
+            if (dir == null || !dir.isDirectory())
+            {
+                report.addProblem(this, "Does not exist: " + resource);
+                continue;
+            }
+
+            File[] files = dir.listFiles();
+            if (files == null)
+                continue;
+            Arrays.sort(files);
+            for (File file : files)
+            {
+                if (_filenameFilter != null && !_filenameFilter.accept(dir, file.getName()))
+                    continue;
+                try
+                {
+                    App app = createApp(file.getPath());
+                    if (app != null)
+                        app.getContextHandler();
+                }
+                catch (Throwable x)
+                {
+                    report.addProblem(this, "Unable to create context for " + file, x);
+                }
+            }
+        }
+    }
+
     public void setMonitoredResources(List<Resource> resources)
     {
         _monitored.clear();
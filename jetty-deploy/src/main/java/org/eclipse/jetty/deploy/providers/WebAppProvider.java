@@ -16,10 +16,12 @@ package org.eclipse.jetty.deploy.providers;
 import java.io.File;
 import java.io.FilenameFilter;
 import java.util.Locale;
+import java.util.Map;
 
 import org.eclipse.jetty.deploy.App;
 import org.eclipse.jetty.deploy.ConfigurationManager;
 import org.eclipse.jetty.deploy.util.FileID;
+import org.eclipse.jetty.deploy.util.VariableExpander;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.util.StringUtil;
@@ -67,6 +69,8 @@ public class WebAppProvider extends ScanningAppProvider
     private String _defaultsDescriptor;
     private File _tempDirectory;
     private String[] _configurationClasses;
+    private boolean _expandVariables = false;
+    private boolean _strictVariables = false;
 
     public class Filter implements FilenameFilter
     {
@@ -163,6 +167,51 @@ public class WebAppProvider extends ScanningAppProvider
         _parentLoaderPriority = parentLoaderPriority;
     }
 
+    /**
+     * Get whether {@code ${env.NAME}} and {@code ${sys.name}} references are expanded
+     * in context XML files and in the properties of the {@link ConfigurationManager}.
+     *
+     * @return whether environment variable and system property references are expanded
+     * @see VariableExpander
+     */
+    @ManagedAttribute("expand environment variable and system property references")
+    public boolean isExpandVariables()
+    {
+        return _expandVariables;
+    }
+
+    /**
+     * Set whether {@code ${env.NAME}} and {@code ${sys.name}} references are expanded.
+     * Expansion is disabled by default, so that existing context XML files keep their meaning.
+     *
+     * @param expandVariables whether environment variable and system property references are expanded
+     */
+    public void setExpandVariables(boolean expandVariables)
+    {
+        _expandVariables = expandVariables;
+    }
+
+    /**
+     * Get whether the deployment fails when a referenced variable is undefined and has no default.
+     *
+     * @return whether undefined variable references fail the deployment
+     */
+    @ManagedAttribute("fail deployment on undefined variable references")
+    public boolean isStrictVariables()
+    {
+        return _strictVariables;
+    }
+
+    /**
+     * Set whether the deployment fails when a referenced variable is undefined and has no default.
+     *
+     * @param strictVariables whether undefined variable references fail the deployment
+     */
+    public void setStrictVariables(boolean strictVariables)
+    {
+        _strictVariables = strictVariables;
+    }
+
     /**
      * Get the defaultsDescriptor.
      *
@@ -280,7 +329,8 @@ public class WebAppProvider extends ScanningAppProvider
         // Handle a context XML file
         if (resource.exists() && FileID.isXmlFile(file))
         {
-            XmlConfiguration xmlc = new XmlConfiguration(resource)
+            VariableExpander expander = _expandVariables ? new VariableExpander(_strictVariables) : null;
+            XmlConfiguration xmlc = new XmlConfiguration(resource, expander == null ? null : expander::expandXml)
             {
                 @Override
                 public void initializeDefaults(Object context)
@@ -302,7 +352,13 @@ public class WebAppProvider extends ScanningAppProvider
             getDeploymentManager().scope(xmlc, resource);
 
             if (getConfigurationManager() != null)
-                xmlc.getProperties().putAll(getConfigurationManager().getProperties());
+            {
+                Map<String, String> properties = getConfigurationManager().getProperties();
+                if (expander == null)
+                    xmlc.getProperties().putAll(properties);
+                else
+                    properties.forEach((name, value) -> xmlc.getProperties().put(name, expander.expand(value)));
+            }
             return (ContextHandler)xmlc.configure();
         }
         // Otherwise it must be a directory or an archive
@@ -0,0 +1,206 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.deploy.util;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Objects;
+import java.util.function.Function;
+import java.util.function.UnaryOperator;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+
+/**
+ * <p>Expands environment variable and system property references in deployment descriptors.</p>
+ * <p>The supported references are:</p>
+ * <ul>
+ *   <li>{@code ${env.NAME}}, replaced with the value of the environment variable {@code NAME}</li>
+ *   <li>{@code ${sys.name}}, replaced with the value of the system property {@code name}</li>
+ *   <li>{@code ${env.NAME:-default}} and {@code ${sys.name:-default}}, replaced with
+ *   {@code default} if the variable or property is not defined</li>
+ *   <li>{@code $${env.NAME}}, replaced with the literal text {@code ${env.NAME}}</li>
+ * </ul>
+ * <p>Other {@code ${...}} expressions, such as references to XML properties, are left untouched,
+ * as well as all the references within XML comments when expanding XML text.</p>
+ * <p>References to undefined variables without a default are left untouched,
+ * unless the expander is {@link #isStrict() strict}, in which case expansion fails.</p>
+ */
+public class VariableExpander
+{
+    private static final Pattern REFERENCE = Pattern.compile("\\$?\\$\\{(env|sys)\\.([^}:]+)(?::-([^}]*))?}");
+
+    private final boolean strict;
+    private final Function<String, String> environment;
+    private final Function<String, String> systemProperties;
+
+    /**
+     * @param strict whether expansion fails if a referenced variable is undefined
+     */
+    public VariableExpander(boolean strict)
+    {
+        this(strict, System::getenv, System::getProperty);
+    }
+
+    /**
+     * @param strict whether expansion fails if a referenced variable is undefined
+     * @param environment the function resolving environment variables
+     * @param systemProperties the function resolving system properties
+     */
+    public VariableExpander(boolean strict, Function<String, String> environment, Function<String, String> systemProperties)
+    {
+        this.strict = strict;
+        this.environment = Objects.requireNonNull(environment);
+        this.systemProperties = Objects.requireNonNull(systemProperties);
+    }
+
+    /**
+     * @return whether expansion fails if a referenced variable is undefined
+     */
+    public boolean isStrict()
+    {
+        return strict;
+    }
+
+    /**
+     * @param text the text to expand
+     * @return the expanded text
+     * @throws IllegalArgumentException if strict and a referenced variable is undefined
+     */
+    public String expand(String text)
+    {
+        if (text == null || !text.contains("${"))
+            return text;
+
+        StringBuilder builder = new StringBuilder(text.length());
+        List<String> undefined = new ArrayList<>();
+        expand(text, UnaryOperator.identity(), builder, undefined);
+        checkUndefined(undefined);
+        return builder.toString();
+    }
+
+    /**
+     * <p>Expands the references in XML text, escaping the values of the variables
+     * and properties so that they can be used both in XML element text and XML
+     * attribute values.</p>
+     * <p>Default values are already XML text, so they are not escaped.
+     * References within XML comments are neither expanded nor checked.</p>
+     *
+     * @param xml the XML text to expand
+     * @return the expanded XML text
+     * @throws IllegalArgumentException if strict and a referenced variable is undefined
+     */
+    public String expandXml(String xml)
+    {
+        if (xml == null || !xml.contains("${"))
+            return xml;
+
+        StringBuilder builder = new StringBuilder(xml.length());
+        List<String> undefined = new ArrayList<>();
+        int offset = 0;
+        while (offset < xml.length())
+        {
+            int start = xml.indexOf("<!--", offset);
+            if (start < 0)
+                start = xml.length();
+            expand(xml.substring(offset, start), VariableExpander::escapeXml, builder, undefined);
+            if (start == xml.length())
+                break;
+            // Copy the comment verbatim.
+            int end = xml.indexOf("-->", start + 4);
+            end = end < 0 ? xml.length() : end + 3;
+            builder.append(xml, start, end);
+            offset = end;
+        }
+        checkUndefined(undefined);
+        return builder.toString();
+    }
+
+    private void expand(String text, UnaryOperator<String> escape, StringBuilder builder, List<String> undefined)
+    {
+        Matcher matcher = REFERENCE.matcher(text);
+        int offset = 0;
+        while (matcher.find())
+        {
+            builder.append(text, offset, matcher.start());
+            offset = matcher.end();
+
+            String reference = matcher.group();
+            if (reference.startsWith("$$"))
+            {
+                builder.append(reference, 1, reference.length());
+                continue;
+            }
+
+            String name = matcher.group(2);
+            String value = "env".equals(matcher.group(1)) ? environment.apply(name) : systemProperties.apply(name);
+            if (value != null)
+            {
+                builder.append(escape.apply(value));
+            }
+            else if (matcher.group(3) != null)
+            {
+                builder.append(matcher.group(3));
+            }
+            else
+            {
+                undefined.add(matcher.group(1) + "." + name);
+                builder.append(reference);
+            }
+        }
+        builder.append(text, offset, text.length());
+    }
+
+    private void checkUndefined(List<String> undefined)
+    {
+        if (strict && !undefined.isEmpty())
+            throw new IllegalArgumentException("Undefined variables " + undefined);
+    }
+
+    private static String escapeXml(String value)
+    {
+        StringBuilder builder = new StringBuilder(value.length());
+        for (int i = 0; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            switch (c)
+            {
+                case '&':
+                    builder.append("&amp;");
+                    break;
+                case '<':
+                    builder.append("&lt;");
+                    break;
+                case '>':
+                    builder.append("&gt;");
+                    break;
+                case '"':
+                    builder.append("&quot;");
+                    break;
+                case '\'':
+                    builder.append("&apos;");
+                    break;
+                default:
+                    builder.append(c);
+                    break;
+            }
+        }
+        return builder.toString();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[strict=%b]", getClass().getSimpleName(), hashCode(), strict);
+    }
+}
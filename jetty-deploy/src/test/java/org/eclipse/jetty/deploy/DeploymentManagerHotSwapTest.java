@@ -0,0 +1,183 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.deploy;
+
+import java.io.IOException;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.ContextHandlerCollection;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class DeploymentManagerHotSwapTest
+{
+    private final CountDownLatch _slowEntered = new CountDownLatch(1);
+    private final CountDownLatch _slowRelease = new CountDownLatch(1);
+    private Server _server;
+    private LocalConnector _connector;
+    private DeploymentManager _deploymentManager;
+    private VersionedAppProvider _provider;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        ContextHandlerCollection contexts = new ContextHandlerCollection();
+        _server.setHandler(contexts);
+
+        _deploymentManager = new DeploymentManager();
+        _deploymentManager.setContexts(contexts);
+        _deploymentManager.setHotSwap(true);
+        _deploymentManager.setDrainTimeout(5000);
+        _provider = new VersionedAppProvider();
+        _deploymentManager.addAppProvider(_provider);
+        _server.addBean(_deploymentManager);
+
+        _server.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _slowRelease.countDown();
+        _server.stop();
+    }
+
+    private String get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request)).getContent();
+    }
+
+    @Test
+    public void testSwapDrainsInFlightRequests() throws Exception
+    {
+        App oldApp = new App(_deploymentManager, _provider, "versioned");
+        _deploymentManager.addApp(oldApp);
+        assertThat(get("/ctx/"), is("v1"));
+
+        LocalConnector.LocalEndPoint slow = _connector.executeRequest("GET /ctx/slow HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n");
+        assertTrue(_slowEntered.await(5, TimeUnit.SECONDS));
+
+        App newApp = new App(_deploymentManager, _provider, "versioned");
+        CompletableFuture<Boolean> swap = CompletableFuture.supplyAsync(() -> _deploymentManager.swapApp(oldApp, newApp));
+
+        // New requests are routed to the new app while the old app drains.
+        long begin = NanoTime.now();
+        while (!"v2".equals(get("/ctx/")))
+        {
+            assertThat(NanoTime.secondsSince(begin), lessThan(5L));
+            Thread.sleep(10);
+        }
+        assertFalse(swap.isDone());
+        assertTrue(oldApp.getContextHandler().isStarted());
+
+        // Completing the in-flight request completes the swap.
+        _slowRelease.countDown();
+        assertThat(HttpTester.parseResponse(slow.getResponse()).getContent(), is("v1"));
+        assertTrue(swap.get(5, TimeUnit.SECONDS));
+
+        assertThat(_deploymentManager.getApps(), contains(newApp));
+        assertTrue(oldApp.getContextHandler().isStopped());
+        assertThat(get("/ctx/"), is("v2"));
+    }
+
+    @Test
+    public void testFailedSwapKeepsOldApp() throws Exception
+    {
+        App oldApp = new App(_deploymentManager, _provider, "versioned");
+        _deploymentManager.addApp(oldApp);
+        assertThat(get("/ctx/"), is("v1"));
+
+        _provider.fail = true;
+        App newApp = new App(_deploymentManager, _provider, "versioned");
+        assertFalse(_deploymentManager.swapApp(oldApp, newApp));
+
+        assertThat(_deploymentManager.getApps(), contains(oldApp));
+        assertThat(get("/ctx/"), is("v1"));
+    }
+
+    private class VersionedAppProvider extends AbstractLifeCycle implements AppProvider
+    {
+        private final AtomicInteger versions = new AtomicInteger();
+        private volatile boolean fail;
+
+        @Override
+        public void setDeploymentManager(DeploymentManager deploymentManager)
+        {
+        }
+
+        @Override
+        public ContextHandler createContextHandler(App app)
+        {
+            String version = "v" + versions.incrementAndGet();
+            boolean failStart = fail;
+            ContextHandler context = new ContextHandler("/ctx")
+            {
+                @Override
+                protected void doStart() throws Exception
+                {
+                    if (failStart)
+                        throw new IllegalStateException("Failed " + version);
+                    super.doStart();
+                }
+            };
+            context.setHandler(new AbstractHandler()
+            {
+                @Override
+                public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+                {
+                    baseRequest.setHandled(true);
+                    if ("/slow".equals(target))
+                    {
+                        _slowEntered.countDown();
+                        try
+                        {
+                            _slowRelease.await(10, TimeUnit.SECONDS);
+                        }
+                        catch (InterruptedException x)
+                        {
+                            Thread.currentThread().interrupt();
+                        }
+                    }
+                    response.getWriter().print(version);
+                }
+            });
+            return context;
+        }
+    }
+}
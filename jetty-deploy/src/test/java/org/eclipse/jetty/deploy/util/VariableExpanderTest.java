@@ -0,0 +1,88 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.deploy.util;
+
+import java.util.Map;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class VariableExpanderTest
+{
+    private static final Map<String, String> ENV = Map.of("DB_URL", "jdbc:h2:mem:test", "MARKUP", "<a & 'b'>");
+    private static final Map<String, String> SYS = Map.of("app.name", "shop");
+
+    private VariableExpander newExpander(boolean strict)
+    {
+        return new VariableExpander(strict, ENV::get, SYS::get);
+    }
+
+    @Test
+    public void testExpand()
+    {
+        VariableExpander expander = newExpander(false);
+        assertThat(expander.expand("url=${env.DB_URL} name=${sys.app.name}"), is("url=jdbc:h2:mem:test name=shop"));
+        assertThat(expander.expand("${env.MISSING:-fallback}/${sys.missing:-}"), is("fallback/"));
+        assertThat(expander.expand("${env.DB_URL:-unused}"), is("jdbc:h2:mem:test"));
+    }
+
+    @Test
+    public void testUntouchedReferences()
+    {
+        VariableExpander expander = newExpander(false);
+        // Non env/sys references, escaped references and undefined variables are not expanded.
+        assertThat(expander.expand("${jetty.base}/$${env.DB_URL}/${env.MISSING}"), is("${jetty.base}/${env.DB_URL}/${env.MISSING}"));
+    }
+
+    @Test
+    public void testStrict()
+    {
+        VariableExpander expander = newExpander(true);
+        assertThat(expander.expand("${env.MISSING:-dft} ${jetty.home}"), is("dft ${jetty.home}"));
+
+        IllegalArgumentException x = assertThrows(IllegalArgumentException.class, () -> expander.expand("${env.MISSING}-${sys.other}"));
+        assertThat(x.getMessage(), containsString("env.MISSING"));
+        assertThat(x.getMessage(), containsString("sys.other"));
+    }
+
+    @Test
+    public void testExpandXml()
+    {
+        VariableExpander expander = newExpander(false);
+        assertThat(expander.expandXml("<Set name=\"title\" value=\"${env.MARKUP}\"/>"), is("<Set name=\"title\" value=\"&lt;a &amp; &apos;b&apos;&gt;\"/>"));
+    }
+
+    @Test
+    public void testExpandXmlDefaultIsNotEscaped()
+    {
+        VariableExpander expander = newExpander(false);
+        // The default value is already XML text.
+        assertThat(expander.expandXml("<Set name=\"title\">${env.MISSING:-a&amp;b}</Set>"), is("<Set name=\"title\">a&amp;b</Set>"));
+    }
+
+    @Test
+    public void testExpandXmlSkipsComments()
+    {
+        VariableExpander expander = newExpander(true);
+        String xml = "<!-- <Set name=\"url\">${env.MISSING}</Set> -->\n<Set name=\"url\">${env.DB_URL}</Set><!--${sys.app.name}-->";
+        assertThat(expander.expandXml(xml), is("<!-- <Set name=\"url\">${env.MISSING}</Set> -->\n<Set name=\"url\">jdbc:h2:mem:test</Set><!--${sys.app.name}-->"));
+
+        // Outside comments, undefined references still fail strict expansion.
+        assertThrows(IllegalArgumentException.class, () -> expander.expandXml("<!-- ${env.DB_URL} --><Set>${env.MISSING}</Set>"));
+    }
+}
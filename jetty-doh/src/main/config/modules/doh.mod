@@ -0,0 +1,40 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Serves DNS queries over HTTPS (RFC 8484) at /dns-query, forwarding
+them to an upstream DNS server and caching the DNS responses.
+RFC 8484 recommends HTTP/2 over TLS, see the ssl and http2 modules.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-doh.xml
+
+[lib]
+lib/jetty-doh-${jetty.version}.jar
+
+[ini-template]
+## The path of the DNS query endpoint.
+# jetty.doh.path=/dns-query
+
+## The host of the upstream DNS server.
+# jetty.doh.upstream.host=127.0.0.1
+
+## The port of the upstream DNS server.
+# jetty.doh.upstream.port=53
+
+## The timeout in milliseconds of an exchange with the upstream DNS server.
+# jetty.doh.upstream.timeout=5000
+
+## Whether DNS queries are always sent over TCP to the upstream DNS server.
+# jetty.doh.upstream.tcpOnly=false
+
+## The max number of cached DNS responses, or 0 to disable caching.
+# jetty.doh.maxCacheSize=4096
+
+## The max time in seconds a DNS response is cached.
+# jetty.doh.maxCacheTTL=3600
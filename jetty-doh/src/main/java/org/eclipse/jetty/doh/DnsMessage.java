@@ -0,0 +1,284 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.doh;
+
+import java.util.ArrayList;
+import java.util.List;
+
+/**
+ * <p>Minimal view of a DNS message in wire format (RFC 1035), exposing
+ * what is needed to forward queries and to cache responses.</p>
+ */
+final class DnsMessage
+{
+    static final int HEADER_LENGTH = 12;
+    static final int MAX_LENGTH = 65535;
+    static final int RCODE_NOERROR = 0;
+    static final int RCODE_NXDOMAIN = 3;
+    private static final int TYPE_OPT = 41;
+    private static final int FLAG_QR = 0x8000;
+    private static final int FLAG_TC = 0x0200;
+    private static final int FLAG_RD = 0x0100;
+    private static final int FLAG_CD = 0x0010;
+    private static final int EDNS_DO = 0x8000;
+    private static final int MAX_POINTERS = 64;
+
+    private final byte[] bytes;
+    private final int flags;
+    private final String questionName;
+    private final int questionType;
+    private final int questionClass;
+    private final boolean dnssecOK;
+    private final int[] ttlOffsets;
+    private final long minTTL;
+
+    private DnsMessage(byte[] bytes, int flags, String questionName, int questionType, int questionClass, boolean dnssecOK, int[] ttlOffsets, long minTTL)
+    {
+        this.bytes = bytes;
+        this.flags = flags;
+        this.questionName = questionName;
+        this.questionType = questionType;
+        this.questionClass = questionClass;
+        this.dnssecOK = dnssecOK;
+        this.ttlOffsets = ttlOffsets;
+        this.minTTL = minTTL;
+    }
+
+    /**
+     * @param bytes the DNS message in wire format
+     * @return the parsed DNS message
+     * @throws IllegalArgumentException if the bytes are not a valid DNS message
+     */
+    static DnsMessage parse(byte[] bytes)
+    {
+        if (bytes.length < HEADER_LENGTH || bytes.length > MAX_LENGTH)
+            throw new IllegalArgumentException("Invalid DNS message length " + bytes.length);
+
+        int flags = getShort(bytes, 2);
+        int questions = getShort(bytes, 4);
+        int records = getShort(bytes, 6) + getShort(bytes, 8) + getShort(bytes, 10);
+
+        int offset = HEADER_LENGTH;
+        String questionName = null;
+        int questionType = -1;
+        int questionClass = -1;
+        for (int i = 0; i < questions; ++i)
+        {
+            StringBuilder name = i == 0 ? new StringBuilder() : null;
+            offset = readName(bytes, offset, name);
+            check(bytes, offset, 4);
+            if (name != null)
+            {
+                questionName = name.length() == 0 ? "." : name.toString();
+                questionType = getShort(bytes, offset);
+                questionClass = getShort(bytes, offset + 2);
+            }
+            offset += 4;
+        }
+
+        boolean dnssecOK = false;
+        List<Integer> ttlOffsets = new ArrayList<>();
+        long minTTL = -1;
+        for (int i = 0; i < records; ++i)
+        {
+            offset = readName(bytes, offset, null);
+            check(bytes, offset, 10);
+            int type = getShort(bytes, offset);
+            if (type == TYPE_OPT)
+            {
+                // The TTL field of the EDNS pseudo-record carries the extended flags.
+                dnssecOK = (getShort(bytes, offset + 6) & EDNS_DO) != 0;
+            }
+            else
+            {
+                ttlOffsets.add(offset + 4);
+                long ttl = getTTL(bytes, offset + 4);
+                minTTL = minTTL < 0 ? ttl : Math.min(minTTL, ttl);
+            }
+            int length = getShort(bytes, offset + 8);
+            offset += 10;
+            check(bytes, offset, length);
+            offset += length;
+        }
+
+        return new DnsMessage(bytes, flags, questions == 1 ? questionName : null, questionType, questionClass, dnssecOK,
+            ttlOffsets.stream().mapToInt(Integer::intValue).toArray(), minTTL);
+    }
+
+    static int getId(byte[] bytes)
+    {
+        return getShort(bytes, 0);
+    }
+
+    static void setId(byte[] bytes, int id)
+    {
+        bytes[0] = (byte)(id >> 8);
+        bytes[1] = (byte)id;
+    }
+
+    byte[] getBytes()
+    {
+        return bytes;
+    }
+
+    int getId()
+    {
+        return getId(bytes);
+    }
+
+    boolean isResponse()
+    {
+        return (flags & FLAG_QR) != 0;
+    }
+
+    boolean isTruncated()
+    {
+        return (flags & FLAG_TC) != 0;
+    }
+
+    int getOpcode()
+    {
+        return (flags >> 11) & 0x0F;
+    }
+
+    int getResponseCode()
+    {
+        return flags & 0x0F;
+    }
+
+    /**
+     * @return the lower case name of the question, or null if the message does not have exactly one question
+     */
+    String getQuestionName()
+    {
+        return questionName;
+    }
+
+    int getQuestionType()
+    {
+        return questionType;
+    }
+
+    int getQuestionClass()
+    {
+        return questionClass;
+    }
+
+    /**
+     * @return the minimum TTL in seconds of the resource records, or -1 if there are no resource records
+     */
+    long getMinTTL()
+    {
+        return minTTL;
+    }
+
+    /**
+     * <p>Returns the key identifying equivalent queries, made of the question and
+     * of the flags that alter the response, but not of the message ID nor of the
+     * EDNS options such as padding.</p>
+     *
+     * @return the cache key of this query, or null if this query is not cacheable
+     */
+    String getCacheKey()
+    {
+        if (questionName == null || getOpcode() != 0)
+            return null;
+        return questionName + " " + questionType + " " + questionClass + " " + (flags & (FLAG_RD | FLAG_CD)) + (dnssecOK ? " DO" : "");
+    }
+
+    /**
+     * @param id the message ID
+     * @param seconds the seconds elapsed since the message was received
+     * @return a copy of this message with the given ID and with the TTLs decreased by the given seconds
+     */
+    byte[] copy(int id, long seconds)
+    {
+        byte[] copy = bytes.clone();
+        setId(copy, id);
+        for (int offset : ttlOffsets)
+        {
+            long ttl = Math.max(0, getTTL(copy, offset) - seconds);
+            copy[offset] = (byte)(ttl >> 24);
+            copy[offset + 1] = (byte)(ttl >> 16);
+            copy[offset + 2] = (byte)(ttl >> 8);
+            copy[offset + 3] = (byte)ttl;
+        }
+        return copy;
+    }
+
+    private static int readName(byte[] bytes, int offset, StringBuilder name)
+    {
+        int end = -1;
+        int pointers = 0;
+        while (true)
+        {
+            check(bytes, offset, 1);
+            int length = bytes[offset] & 0xFF;
+            if ((length & 0xC0) == 0xC0)
+            {
+                check(bytes, offset, 2);
+                if (end < 0)
+                    end = offset + 2;
+                if (++pointers > MAX_POINTERS)
+                    throw new IllegalArgumentException("Invalid DNS name compression");
+                offset = ((length & 0x3F) << 8) | (bytes[offset + 1] & 0xFF);
+                continue;
+            }
+            if ((length & 0xC0) != 0)
+                throw new IllegalArgumentException("Invalid DNS label type");
+            if (length == 0)
+                return end < 0 ? offset + 1 : end;
+            check(bytes, offset + 1, length);
+            if (name != null)
+            {
+                if (name.length() > 0)
+                    name.append('.');
+                for (int i = offset + 1; i <= offset + length; ++i)
+                {
+                    char c = (char)(bytes[i] & 0xFF);
+                    if (c >= 'A' && c <= 'Z')
+                        c += 'a' - 'A';
+                    if (c == '.' || c == '\\')
+                        name.append('\\');
+                    name.append(c);
+                }
+            }
+            offset += 1 + length;
+        }
+    }
+
+    private static long getTTL(byte[] bytes, int offset)
+    {
+        long ttl = ((long)getShort(bytes, offset) << 16) | getShort(bytes, offset + 2);
+        // RFC 2181, section 8: TTLs with the most significant bit set are treated as zero.
+        return ttl > Integer.MAX_VALUE ? 0 : ttl;
+    }
+
+    private static int getShort(byte[] bytes, int offset)
+    {
+        return ((bytes[offset] & 0xFF) << 8) | (bytes[offset + 1] & 0xFF);
+    }
+
+    private static void check(byte[] bytes, int offset, int length)
+    {
+        if (offset + length > bytes.length)
+            throw new IllegalArgumentException("Truncated DNS message");
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[id=%d,question=%s/%d/%d,rcode=%d]", getClass().getSimpleName(), hashCode(), getId(), questionName, questionType, questionClass, getResponseCode());
+    }
+}
@@ -0,0 +1,35 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.doh;
+
+import org.eclipse.jetty.util.Promise;
+
+/**
+ * <p>Resolves DNS queries in wire format (RFC 1035) on behalf of {@link DoHHandler}.</p>
+ *
+ * @see UpstreamDnsResolver
+ */
+@FunctionalInterface
+public interface DnsResolver
+{
+    /**
+     * <p>Resolves the given DNS query.</p>
+     * <p>The promise may be completed asynchronously, either with the DNS response,
+     * that must have the same message ID as the query, or with the failure.</p>
+     *
+     * @param query the DNS query in wire format
+     * @param promise the promise to complete with the DNS response in wire format
+     */
+    void resolve(byte[] query, Promise<byte[]> promise);
+}
@@ -0,0 +1,385 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.doh;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.net.SocketTimeoutException;
+import java.util.Base64;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncContext;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that serves DNS queries over HTTPS (DoH) as specified
+ * by RFC 8484, by default at {@code /dns-query}, passing all other requests to the
+ * wrapped handler.</p>
+ * <p>DNS queries are accepted either as {@code GET} requests with the query encoded
+ * in base64url in the {@code dns} query parameter, or as {@code POST} requests with
+ * the query as content of type {@code application/dns-message}, and are resolved by
+ * the configured {@link DnsResolver}, typically an {@link UpstreamDnsResolver}.
+ * The DNS response is sent back as content of type {@code application/dns-message},
+ * with a {@code Cache-Control} freshness lifetime derived from the smallest TTL of
+ * its resource records.</p>
+ * <p>Successful and negative DNS responses are cached, keyed by the question, for
+ * the smallest TTL of their resource records capped to {@link #getMaxCacheTTL()},
+ * and are served from the cache with their TTLs decreased by their age.</p>
+ * <p>This handler works with any HTTP version, but RFC 8484 recommends HTTP/2 as the
+ * minimum version, so it is typically deployed behind an HTTP/2 (or HTTP/3) connector
+ * configured with TLS.</p>
+ */
+@ManagedObject("DNS over HTTPS handler")
+public class DoHHandler extends HandlerWrapper
+{
+    public static final String DNS_MESSAGE = "application/dns-message";
+    private static final Logger LOG = LoggerFactory.getLogger(DoHHandler.class);
+
+    private final AutoLock lock = new AutoLock();
+    private final Map<String, CacheEntry> cache = new LinkedHashMap<>(16, 0.75F, true);
+    private final LongAdder queries = new LongAdder();
+    private final LongAdder cacheHits = new LongAdder();
+    private final LongAdder failures = new LongAdder();
+    private final DnsResolver resolver;
+    private String path = "/dns-query";
+    private int maxCacheSize = 4096;
+    private long maxCacheTTL = 3600;
+
+    public DoHHandler(DnsResolver resolver)
+    {
+        this.resolver = Objects.requireNonNull(resolver);
+        addBean(resolver);
+    }
+
+    public DnsResolver getDnsResolver()
+    {
+        return resolver;
+    }
+
+    @ManagedAttribute("The path of the DNS query endpoint")
+    public String getPath()
+    {
+        return path;
+    }
+
+    public void setPath(String path)
+    {
+        if (path == null || !path.startsWith("/"))
+            throw new IllegalArgumentException("Invalid path " + path);
+        this.path = path;
+    }
+
+    @ManagedAttribute("The max number of cached DNS responses, or 0 to disable caching")
+    public int getMaxCacheSize()
+    {
+        return maxCacheSize;
+    }
+
+    public void setMaxCacheSize(int maxCacheSize)
+    {
+        this.maxCacheSize = Math.max(0, maxCacheSize);
+        if (this.maxCacheSize == 0)
+            clearCache();
+    }
+
+    @ManagedAttribute("The max time in seconds a DNS response is cached")
+    public long getMaxCacheTTL()
+    {
+        return maxCacheTTL;
+    }
+
+    public void setMaxCacheTTL(long maxCacheTTL)
+    {
+        this.maxCacheTTL = maxCacheTTL;
+    }
+
+    @ManagedAttribute("The number of cached DNS responses")
+    public int getCacheSize()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return cache.size();
+        }
+    }
+
+    @ManagedAttribute("The number of DNS queries received")
+    public long getQueries()
+    {
+        return queries.sum();
+    }
+
+    @ManagedAttribute("The number of DNS queries answered from the cache")
+    public long getCacheHits()
+    {
+        return cacheHits.sum();
+    }
+
+    @ManagedAttribute("The number of DNS queries that failed to resolve")
+    public long getFailures()
+    {
+        return failures.sum();
+    }
+
+    @ManagedOperation(value = "Clears the DNS response cache", impact = "ACTION")
+    public void clearCache()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            cache.clear();
+        }
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        queries.reset();
+        cacheHits.reset();
+        failures.reset();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        clearCache();
+        super.doStop();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (!target.equals(path))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setHandled(true);
+        byte[] query;
+        String method = request.getMethod();
+        if (HttpMethod.GET.is(method))
+        {
+            String dns = request.getParameter("dns");
+            if (dns == null)
+            {
+                response.sendError(HttpStatus.BAD_REQUEST_400, "Missing dns parameter");
+                return;
+            }
+            try
+            {
+                query = Base64.getUrlDecoder().decode(dns);
+            }
+            catch (IllegalArgumentException x)
+            {
+                response.sendError(HttpStatus.BAD_REQUEST_400, "Invalid dns parameter");
+                return;
+            }
+        }
+        else if (HttpMethod.POST.is(method))
+        {
+            if (!DNS_MESSAGE.equalsIgnoreCase(MimeTypes.getContentTypeWithoutCharset(request.getContentType())))
+            {
+                response.sendError(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415);
+                return;
+            }
+            query = readQuery(request);
+            if (query == null)
+            {
+                response.sendError(HttpStatus.PAYLOAD_TOO_LARGE_413);
+                return;
+            }
+        }
+        else
+        {
+            response.setHeader(HttpHeader.ALLOW.asString(), "GET, POST");
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+
+        DnsMessage message;
+        try
+        {
+            message = DnsMessage.parse(query);
+            if (message.isResponse())
+                throw new IllegalArgumentException("Not a DNS query");
+        }
+        catch (IllegalArgumentException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid DNS query", x);
+            response.sendError(HttpStatus.BAD_REQUEST_400, "Invalid DNS query");
+            return;
+        }
+
+        queries.increment();
+        String key = maxCacheSize > 0 ? message.getCacheKey() : null;
+        if (key != null)
+        {
+            CacheEntry entry = getCacheEntry(key);
+            if (entry != null)
+            {
+                long age = NanoTime.secondsSince(entry.created);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Cache hit {} age={}s", key, age);
+                cacheHits.increment();
+                send(response, entry.response.copy(message.getId(), age), Math.max(0, entry.response.getMinTTL() - age));
+                return;
+            }
+        }
+
+        AsyncContext asyncContext = request.startAsync();
+        asyncContext.setTimeout(0);
+        resolver.resolve(query, new Promise<>()
+        {
+            @Override
+            public void succeeded(byte[] result)
+            {
+                try
+                {
+                    DnsMessage reply = DnsMessage.parse(result);
+                    if (!reply.isResponse() || reply.getId() != message.getId())
+                        throw new IllegalArgumentException("Not a DNS response to " + message);
+                    if (key != null)
+                        cache(key, reply);
+                    send(response, result, reply.getMinTTL());
+                    asyncContext.complete();
+                }
+                catch (Throwable x)
+                {
+                    failed(x);
+                }
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not resolve {}", message, x);
+                failures.increment();
+                try
+                {
+                    boolean timeout = x instanceof SocketTimeoutException || x instanceof TimeoutException;
+                    response.sendError(timeout ? HttpStatus.GATEWAY_TIMEOUT_504 : HttpStatus.BAD_GATEWAY_502);
+                }
+                catch (Throwable t)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not send error response", t);
+                }
+                finally
+                {
+                    asyncContext.complete();
+                }
+            }
+        });
+    }
+
+    private byte[] readQuery(HttpServletRequest request) throws IOException
+    {
+        if (request.getContentLengthLong() > DnsMessage.MAX_LENGTH)
+            return null;
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        InputStream input = request.getInputStream();
+        byte[] buffer = new byte[4096];
+        int read;
+        while ((read = input.read(buffer)) >= 0)
+        {
+            output.write(buffer, 0, read);
+            if (output.size() > DnsMessage.MAX_LENGTH)
+                return null;
+        }
+        return output.toByteArray();
+    }
+
+    private CacheEntry getCacheEntry(String key)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            CacheEntry entry = cache.get(key);
+            if (entry != null && NanoTime.secondsSince(entry.created) >= entry.ttl)
+            {
+                cache.remove(key);
+                entry = null;
+            }
+            return entry;
+        }
+    }
+
+    private void cache(String key, DnsMessage reply)
+    {
+        int rcode = reply.getResponseCode();
+        if (reply.isTruncated() || (rcode != DnsMessage.RCODE_NOERROR && rcode != DnsMessage.RCODE_NXDOMAIN))
+            return;
+        long ttl = Math.min(reply.getMinTTL(), maxCacheTTL);
+        if (ttl <= 0)
+            return;
+        try (AutoLock l = lock.lock())
+        {
+            cache.put(key, new CacheEntry(reply, ttl));
+            while (cache.size() > maxCacheSize)
+            {
+                cache.remove(cache.keySet().iterator().next());
+            }
+        }
+    }
+
+    private void send(HttpServletResponse response, byte[] content, long maxAge) throws IOException
+    {
+        response.setStatus(HttpStatus.OK_200);
+        response.setContentType(DNS_MESSAGE);
+        if (maxAge >= 0)
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "max-age=" + maxAge);
+        response.setContentLength(content.length);
+        response.getOutputStream().write(content);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[path=%s,resolver=%s]", getClass().getSimpleName(), hashCode(), path, resolver);
+    }
+
+    private static class CacheEntry
+    {
+        private final long created = NanoTime.now();
+        private final DnsMessage response;
+        private final long ttl;
+
+        private CacheEntry(DnsMessage response, long ttl)
+        {
+            this.response = response;
+            this.ttl = ttl;
+        }
+    }
+}
@@ -0,0 +1,209 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.doh;
+
+import java.io.DataInputStream;
+import java.io.IOException;
+import java.io.OutputStream;
+import java.net.DatagramPacket;
+import java.net.DatagramSocket;
+import java.net.InetSocketAddress;
+import java.net.Socket;
+import java.net.SocketTimeoutException;
+import java.util.Arrays;
+import java.util.Objects;
+import java.util.concurrent.Executor;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link DnsResolver} that forwards DNS queries to an upstream DNS server.</p>
+ * <p>Queries are sent over UDP and are retried over TCP when the upstream
+ * server truncates the response, or always over TCP if so configured.
+ * Each query is sent with a random message ID, so that responses cannot
+ * be easily spoofed, and the response is returned with the original ID.</p>
+ * <p>The exchanges with the upstream server are blocking and are performed
+ * by the given {@link Executor}, usually the server thread pool.</p>
+ */
+@ManagedObject("Upstream DNS resolver")
+public class UpstreamDnsResolver implements DnsResolver
+{
+    private static final Logger LOG = LoggerFactory.getLogger(UpstreamDnsResolver.class);
+
+    private final Executor executor;
+    private final InetSocketAddress address;
+    private long timeout = 5000;
+    private boolean tcpOnly;
+
+    public UpstreamDnsResolver(Executor executor, String host, int port)
+    {
+        this(executor, new InetSocketAddress(host, port));
+    }
+
+    public UpstreamDnsResolver(Executor executor, InetSocketAddress address)
+    {
+        this.executor = Objects.requireNonNull(executor);
+        this.address = Objects.requireNonNull(address);
+    }
+
+    @ManagedAttribute("The address of the upstream DNS server")
+    public String getAddress()
+    {
+        return address.toString();
+    }
+
+    @ManagedAttribute("The timeout in milliseconds of an exchange with the upstream DNS server")
+    public long getTimeout()
+    {
+        return timeout;
+    }
+
+    public void setTimeout(long timeout)
+    {
+        if (timeout <= 0)
+            throw new IllegalArgumentException("Invalid timeout " + timeout);
+        this.timeout = timeout;
+    }
+
+    @ManagedAttribute("Whether queries are always sent over TCP")
+    public boolean isTcpOnly()
+    {
+        return tcpOnly;
+    }
+
+    public void setTcpOnly(boolean tcpOnly)
+    {
+        this.tcpOnly = tcpOnly;
+    }
+
+    @Override
+    public void resolve(byte[] query, Promise<byte[]> promise)
+    {
+        executor.execute(() ->
+        {
+            byte[] response;
+            try
+            {
+                response = exchange(query);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Failed DNS exchange with {}", address, x);
+                promise.failed(x);
+                return;
+            }
+            promise.succeeded(response);
+        });
+    }
+
+    /**
+     * <p>Performs a blocking exchange with the upstream DNS server.</p>
+     *
+     * @param query the DNS query in wire format
+     * @return the DNS response in wire format, with the same message ID as the query
+     * @throws IOException if the exchange fails
+     */
+    protected byte[] exchange(byte[] query) throws IOException
+    {
+        int originalId = DnsMessage.getId(query);
+        int id = ThreadLocalRandom.current().nextInt(0x10000);
+        byte[] upstreamQuery = query.clone();
+        DnsMessage.setId(upstreamQuery, id);
+
+        byte[] response = null;
+        if (!tcpOnly)
+        {
+            response = exchangeUDP(upstreamQuery, id);
+            if (DnsMessage.parse(response).isTruncated())
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Truncated UDP response from {}, retrying over TCP", address);
+                response = null;
+            }
+        }
+        if (response == null)
+            response = exchangeTCP(upstreamQuery, id);
+
+        DnsMessage.setId(response, originalId);
+        return response;
+    }
+
+    private byte[] exchangeUDP(byte[] query, int id) throws IOException
+    {
+        try (DatagramSocket socket = new DatagramSocket())
+        {
+            socket.connect(address);
+            socket.send(new DatagramPacket(query, query.length));
+            byte[] buffer = new byte[DnsMessage.MAX_LENGTH];
+            long deadline = NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(timeout);
+            while (true)
+            {
+                long remaining = NanoTime.millisUntil(deadline);
+                if (remaining <= 0)
+                    throw new SocketTimeoutException("DNS query to " + address + " timed out");
+                socket.setSoTimeout((int)Math.min(remaining, Integer.MAX_VALUE));
+                DatagramPacket packet = new DatagramPacket(buffer, buffer.length);
+                socket.receive(packet);
+                byte[] response = Arrays.copyOf(packet.getData(), packet.getLength());
+                if (isResponse(response, id))
+                    return response;
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Ignoring unexpected UDP packet from {}", address);
+            }
+        }
+    }
+
+    private byte[] exchangeTCP(byte[] query, int id) throws IOException
+    {
+        try (Socket socket = new Socket())
+        {
+            socket.connect(address, (int)Math.min(timeout, Integer.MAX_VALUE));
+            socket.setSoTimeout((int)Math.min(timeout, Integer.MAX_VALUE));
+            // Over TCP, each message is prefixed by its length, see RFC 1035, section 4.2.2.
+            byte[] frame = new byte[2 + query.length];
+            frame[0] = (byte)(query.length >> 8);
+            frame[1] = (byte)query.length;
+            System.arraycopy(query, 0, frame, 2, query.length);
+            OutputStream output = socket.getOutputStream();
+            output.write(frame);
+            output.flush();
+
+            DataInputStream input = new DataInputStream(socket.getInputStream());
+            byte[] response = new byte[input.readUnsignedShort()];
+            input.readFully(response);
+            if (!isResponse(response, id))
+                throw new IOException("Unexpected DNS response from " + address);
+            return response;
+        }
+    }
+
+    private static boolean isResponse(byte[] bytes, int id)
+    {
+        return bytes.length >= DnsMessage.HEADER_LENGTH && DnsMessage.getId(bytes) == id && (bytes[2] & 0x80) != 0;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[address=%s,tcpOnly=%b]", getClass().getSimpleName(), hashCode(), address, tcpOnly);
+    }
+}
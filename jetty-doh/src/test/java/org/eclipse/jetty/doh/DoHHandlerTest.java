@@ -0,0 +1,290 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.doh;
+
+import java.io.ByteArrayOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.net.DatagramPacket;
+import java.net.DatagramSocket;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.ServerSocket;
+import java.net.Socket;
+import java.net.SocketTimeoutException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.Arrays;
+import java.util.Base64;
+import java.util.concurrent.atomic.AtomicInteger;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.BufferUtil;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class DoHHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private DoHHandler dohHandler;
+
+    private void start(DnsResolver resolver) throws Exception
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        dohHandler = new DoHHandler(resolver);
+        server.setHandler(dohHandler);
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (server != null)
+            server.stop();
+    }
+
+    private static byte[] query(int id, String name)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        // ID, flags with RD, one question.
+        output.writeBytes(new byte[]{(byte)(id >> 8), (byte)id, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0});
+        for (String label : name.split("\\."))
+        {
+            output.write(label.length());
+            output.writeBytes(label.getBytes(StandardCharsets.US_ASCII));
+        }
+        // Root label, type A, class IN.
+        output.writeBytes(new byte[]{0, 0, 1, 0, 1});
+        return output.toByteArray();
+    }
+
+    private static byte[] response(byte[] query, int ttl, boolean truncated)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        output.writeBytes(query);
+        byte[] bytes = output.toByteArray();
+        bytes[2] |= (byte)(0x80 | (truncated ? 0x02 : 0));
+        bytes[3] = (byte)0x80;
+        if (truncated)
+            return bytes;
+        bytes[7] = 1;
+        output.reset();
+        output.writeBytes(bytes);
+        // Compressed pointer to the question name, type A, class IN, TTL, 4 bytes of RDATA.
+        output.writeBytes(new byte[]{(byte)0xC0, 0x0C, 0, 1, 0, 1,
+            (byte)(ttl >> 24), (byte)(ttl >> 16), (byte)(ttl >> 8), (byte)ttl, 0, 4, 1, 2, 3, 4});
+        return output.toByteArray();
+    }
+
+    private HttpTester.Response get(byte[] query) throws Exception
+    {
+        String dns = Base64.getUrlEncoder().withoutPadding().encodeToString(query);
+        return get("/dns-query?dns=" + dns);
+    }
+
+    private HttpTester.Response get(String uri) throws Exception
+    {
+        String request = "GET " + uri + " HTTP/1.1\r\nHost: localhost\r\nAccept: application/dns-message\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    private HttpTester.Response post(String contentType, byte[] content) throws Exception
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        output.writeBytes(("POST /dns-query HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Type: " + contentType + "\r\n" +
+            "Content-Length: " + content.length + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n").getBytes(StandardCharsets.ISO_8859_1));
+        output.writeBytes(content);
+        ByteBuffer response = connector.getResponse(BufferUtil.toBuffer(output.toByteArray()));
+        return HttpTester.parseResponse(response);
+    }
+
+    private static int id(byte[] bytes)
+    {
+        return ((bytes[0] & 0xFF) << 8) | (bytes[1] & 0xFF);
+    }
+
+    private static int ttl(byte[] response)
+    {
+        int offset = response.length - 10;
+        return ByteBuffer.wrap(response, offset, 4).getInt();
+    }
+
+    @Test
+    public void testGetAndPostWithCache() throws Exception
+    {
+        AtomicInteger resolutions = new AtomicInteger();
+        start((query, promise) ->
+        {
+            resolutions.incrementAndGet();
+            promise.succeeded(response(query, 300, false));
+        });
+
+        // RFC 8484 recommends clients to use ID 0.
+        HttpTester.Response response = get(query(0, "www.example.com"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), is(DoHHandler.DNS_MESSAGE));
+        assertThat(response.get(HttpHeader.CACHE_CONTROL), is("max-age=300"));
+        byte[] content = response.getContentBytes();
+        assertThat(id(content), is(0));
+        assertThat(ttl(content), is(300));
+        assertThat(dohHandler.getCacheSize(), is(1));
+
+        // Same question with a different ID and a different case is a cache hit.
+        response = post(DoHHandler.DNS_MESSAGE, query(1234, "WWW.Example.com"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        content = response.getContentBytes();
+        assertThat(id(content), is(1234));
+        assertThat(Arrays.copyOfRange(content, content.length - 4, content.length), is(new byte[]{1, 2, 3, 4}));
+        assertThat(resolutions.get(), is(1));
+        assertThat(dohHandler.getQueries(), is(2L));
+        assertThat(dohHandler.getCacheHits(), is(1L));
+
+        // A different question is resolved.
+        assertThat(get(query(0, "example.org")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(resolutions.get(), is(2));
+
+        dohHandler.clearCache();
+        assertThat(get(query(0, "www.example.com")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(resolutions.get(), is(3));
+    }
+
+    @Test
+    public void testZeroTTLNotCached() throws Exception
+    {
+        AtomicInteger resolutions = new AtomicInteger();
+        start((query, promise) ->
+        {
+            resolutions.incrementAndGet();
+            promise.succeeded(response(query, 0, false));
+        });
+
+        for (int i = 0; i < 2; ++i)
+        {
+            HttpTester.Response response = get(query(0, "www.example.com"));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.get(HttpHeader.CACHE_CONTROL), is("max-age=0"));
+        }
+        assertThat(resolutions.get(), is(2));
+        assertThat(dohHandler.getCacheSize(), is(0));
+    }
+
+    @Test
+    public void testInvalidRequests() throws Exception
+    {
+        start((query, promise) -> promise.succeeded(response(query, 60, false)));
+
+        assertThat(get("/dns-query").getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(get("/dns-query?dns=%21%21").getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(get(new byte[]{1, 2, 3}).getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(get(response(query(0, "example.com"), 60, false)).getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(post("application/octet-stream", query(0, "example.com")).getStatus(), is(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415));
+
+        String response = connector.getResponse("PUT /dns-query HTTP/1.1\r\nHost: localhost\r\nContent-Length: 0\r\nConnection: close\r\n\r\n");
+        assertThat(HttpTester.parseResponse(response).getStatus(), is(HttpStatus.METHOD_NOT_ALLOWED_405));
+
+        // Other paths are not handled.
+        assertThat(get("/other").getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testResolverFailures() throws Exception
+    {
+        start((query, promise) ->
+        {
+            if (id(query) == 1)
+                promise.failed(new SocketTimeoutException("explicitly_thrown_by_test"));
+            else
+                promise.failed(new IllegalStateException("explicitly_thrown_by_test"));
+        });
+
+        assertThat(get(query(1, "example.com")).getStatus(), is(HttpStatus.GATEWAY_TIMEOUT_504));
+        assertThat(get(query(2, "example.com")).getStatus(), is(HttpStatus.BAD_GATEWAY_502));
+        assertThat(dohHandler.getFailures(), is(2L));
+    }
+
+    @Test
+    public void testUpstreamTruncatedResponseRetriedOverTCP() throws Exception
+    {
+        InetAddress loopback = InetAddress.getLoopbackAddress();
+        try (ServerSocket tcp = new ServerSocket(0, 1, loopback);
+             DatagramSocket udp = new DatagramSocket(new InetSocketAddress(loopback, tcp.getLocalPort())))
+        {
+            Thread udpServer = new Thread(() ->
+            {
+                try
+                {
+                    byte[] buffer = new byte[512];
+                    DatagramPacket packet = new DatagramPacket(buffer, buffer.length);
+                    udp.receive(packet);
+                    byte[] reply = response(Arrays.copyOf(buffer, packet.getLength()), 120, true);
+                    udp.send(new DatagramPacket(reply, reply.length, packet.getSocketAddress()));
+                }
+                catch (Exception ignored)
+                {
+                }
+            });
+            udpServer.start();
+            Thread tcpServer = new Thread(() ->
+            {
+                try (Socket socket = tcp.accept())
+                {
+                    DataInputStream input = new DataInputStream(socket.getInputStream());
+                    byte[] query = new byte[input.readUnsignedShort()];
+                    input.readFully(query);
+                    byte[] reply = response(query, 120, false);
+                    DataOutputStream output = new DataOutputStream(socket.getOutputStream());
+                    output.writeShort(reply.length);
+                    output.write(reply);
+                    output.flush();
+                }
+                catch (Exception ignored)
+                {
+                }
+            });
+            tcpServer.start();
+
+            server = new Server();
+            UpstreamDnsResolver resolver = new UpstreamDnsResolver(server.getThreadPool(), new InetSocketAddress(loopback, tcp.getLocalPort()));
+            connector = new LocalConnector(server);
+            server.addConnector(connector);
+            dohHandler = new DoHHandler(resolver);
+            server.setHandler(dohHandler);
+            server.start();
+
+            HttpTester.Response response = post(DoHHandler.DNS_MESSAGE, query(4321, "www.example.com"));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            byte[] content = response.getContentBytes();
+            // The upstream query ID is random, but the original ID is restored.
+            assertThat(id(content), is(4321));
+            assertThat(ttl(content), is(120));
+
+            udpServer.join(5000);
+            tcpServer.join(5000);
+        }
+    }
+}
@@ -128,4 +128,27 @@ public class FCGI
         {
         }
     }
+
+    /**
+     * <p>The names of the values that can be queried with a {@link FrameType#GET_VALUES} frame.</p>
+     */
+    public static class Values
+    {
+        /**
+         * The maximum number of concurrent connections the application will accept.
+         */
+        public static final String MAX_CONNS = "FCGI_MAX_CONNS";
+        /**
+         * The maximum number of concurrent requests the application will accept.
+         */
+        public static final String MAX_REQS = "FCGI_MAX_REQS";
+        /**
+         * "0" if the application does not multiplex connections, "1" otherwise.
+         */
+        public static final String MPXS_CONNS = "FCGI_MPXS_CONNS";
+
+        private Values()
+        {
+        }
+    }
 }
@@ -16,6 +16,8 @@ package org.eclipse.jetty.fcgi.client.http;
 import java.io.IOException;
 import java.util.List;
 import java.util.Map;
+import java.util.TreeMap;
+import java.util.concurrent.atomic.LongAdder;
 
 import org.eclipse.jetty.client.AbstractConnectorHttpClientTransport;
 import org.eclipse.jetty.client.DuplexConnectionPool;
@@ -23,8 +25,11 @@ import org.eclipse.jetty.client.DuplexHttpDestination;
 import org.eclipse.jetty.client.HttpClient;
 import org.eclipse.jetty.client.HttpDestination;
 import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.MultiplexConnectionPool;
+import org.eclipse.jetty.client.MultiplexHttpDestination;
 import org.eclipse.jetty.client.Origin;
 import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.client.api.Destination;
 import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.http.HttpFields;
@@ -34,6 +39,7 @@ import org.eclipse.jetty.util.ProcessorUtils;
 import org.eclipse.jetty.util.Promise;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -42,7 +48,12 @@ public class HttpClientTransportOverFCGI extends AbstractConnectorHttpClientTran
 {
     private static final Logger LOG = LoggerFactory.getLogger(HttpClientTransportOverFCGI.class);
 
+    private final LongAdder healthChecks = new LongAdder();
+    private final LongAdder healthCheckFailures = new LongAdder();
     private final String scriptRoot;
+    private boolean multiplexed;
+    private long healthCheckInterval;
+    private long healthCheckTimeout = 5000;
 
     public HttpClientTransportOverFCGI(String scriptRoot)
     {
@@ -63,6 +74,8 @@ public class HttpClientTransportOverFCGI extends AbstractConnectorHttpClientTran
         {
             HttpClient httpClient = getHttpClient();
             int maxConnections = httpClient.getMaxConnectionsPerDestination();
+            if (isMultiplexed())
+                return new MultiplexConnectionPool(destination, maxConnections, destination, 1);
             return new DuplexConnectionPool(destination, maxConnections, destination);
         });
     }
@@ -73,6 +86,99 @@ public class HttpClientTransportOverFCGI extends AbstractConnectorHttpClientTran
         return scriptRoot;
     }
 
+    @ManagedAttribute("Whether requests are multiplexed on connections to backends that support it")
+    public boolean isMultiplexed()
+    {
+        return multiplexed;
+    }
+
+    /**
+     * <p>Sets whether requests may be multiplexed on the same connection.</p>
+     * <p>When enabled, every new connection queries the backend with a
+     * FCGI_GET_VALUES frame, and multiplexes requests only if the backend
+     * replies that it supports multiplexing (FCGI_MPXS_CONNS=1), up to the
+     * FCGI_MAX_REQS value replied by the backend.</p>
+     * <p>This property must be set before sending requests.</p>
+     *
+     * @param multiplexed whether requests may be multiplexed
+     */
+    public void setMultiplexed(boolean multiplexed)
+    {
+        this.multiplexed = multiplexed;
+    }
+
+    @ManagedAttribute("The interval in ms of the health checks of idle connections, or 0 to disable health checks")
+    public long getHealthCheckInterval()
+    {
+        return healthCheckInterval;
+    }
+
+    /**
+     * <p>Sets the interval of the health checks of idle connections.</p>
+     * <p>Health checks send a FCGI_GET_VALUES frame to the backend, and close
+     * the connection if the backend does not reply within the
+     * {@link #setHealthCheckTimeout(long) health check timeout}, so that
+     * connections to dead backend workers are removed from the connection pool.</p>
+     *
+     * @param healthCheckInterval the interval in ms, or 0 to disable health checks
+     */
+    public void setHealthCheckInterval(long healthCheckInterval)
+    {
+        this.healthCheckInterval = healthCheckInterval;
+    }
+
+    @ManagedAttribute("The timeout in ms of the health checks of idle connections")
+    public long getHealthCheckTimeout()
+    {
+        return healthCheckTimeout;
+    }
+
+    public void setHealthCheckTimeout(long healthCheckTimeout)
+    {
+        this.healthCheckTimeout = healthCheckTimeout;
+    }
+
+    @ManagedAttribute(value = "The number of health checks performed", readonly = true)
+    public long getHealthChecks()
+    {
+        return healthChecks.sum();
+    }
+
+    @ManagedAttribute(value = "The number of failed health checks, that caused the connection to be closed", readonly = true)
+    public long getHealthCheckFailures()
+    {
+        return healthCheckFailures.sum();
+    }
+
+    @ManagedAttribute(value = "The number of queued requests per backend", readonly = true)
+    public Map<String, Integer> getQueuedRequestsPerBackend()
+    {
+        Map<String, Integer> result = new TreeMap<>();
+        for (Destination destination : getHttpClient().getDestinations())
+        {
+            if (destination instanceof HttpDestination)
+            {
+                Origin.Address address = ((HttpDestination)destination).getOrigin().getAddress();
+                result.merge(address.asString(), ((HttpDestination)destination).getQueuedRequestCount(), Integer::sum);
+            }
+        }
+        return result;
+    }
+
+    @ManagedOperation(value = "Resets the health check statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        healthChecks.reset();
+        healthCheckFailures.reset();
+    }
+
+    void onHealthCheck(boolean success)
+    {
+        healthChecks.increment();
+        if (!success)
+            healthCheckFailures.increment();
+    }
+
     @Override
     public Origin newOrigin(HttpRequest request)
     {
@@ -82,6 +188,8 @@ public class HttpClientTransportOverFCGI extends AbstractConnectorHttpClientTran
     @Override
     public HttpDestination newHttpDestination(Origin origin)
     {
+        if (isMultiplexed())
+            return new MultiplexHttpDestination(getHttpClient(), origin);
         return new DuplexHttpDestination(getHttpClient(), origin);
     }
 
@@ -14,16 +14,25 @@
 package org.eclipse.jetty.fcgi.client.http;
 
 import java.io.EOFException;
+import java.net.SocketAddress;
 import java.nio.ByteBuffer;
 import java.nio.channels.AsynchronousCloseException;
-import java.util.Collections;
+import java.util.Collection;
 import java.util.Iterator;
 import java.util.LinkedList;
+import java.util.List;
+import java.util.Map;
+import java.util.Queue;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
 import java.util.concurrent.atomic.AtomicBoolean;
 
+import org.eclipse.jetty.client.ConnectionPool;
 import org.eclipse.jetty.client.HttpChannel;
 import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpClientTransport;
 import org.eclipse.jetty.client.HttpConnection;
 import org.eclipse.jetty.client.HttpDestination;
 import org.eclipse.jetty.client.HttpExchange;
@@ -34,6 +43,7 @@ import org.eclipse.jetty.client.api.Connection;
 import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.client.api.Response;
 import org.eclipse.jetty.fcgi.FCGI;
+import org.eclipse.jetty.fcgi.generator.ClientGenerator;
 import org.eclipse.jetty.fcgi.generator.Flusher;
 import org.eclipse.jetty.fcgi.parser.ClientParser;
 import org.eclipse.jetty.http.HttpField;
@@ -49,25 +59,32 @@ import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.Promise;
 import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
-public class HttpConnectionOverFCGI extends AbstractConnection implements IConnection, Attachable
+public class HttpConnectionOverFCGI extends AbstractConnection implements IConnection, Attachable, ConnectionPool.Multiplexable
 {
     private static final Logger LOG = LoggerFactory.getLogger(HttpConnectionOverFCGI.class);
+    private static final List<String> VALUES = List.of(FCGI.Values.MAX_CONNS, FCGI.Values.MAX_REQS, FCGI.Values.MPXS_CONNS);
 
     private final RetainableByteBufferPool networkByteBufferPool;
     private final AutoLock lock = new AutoLock();
     private final LinkedList<Integer> requests = new LinkedList<>();
+    private final Map<Integer, HttpChannelOverFCGI> activeChannels = new ConcurrentHashMap<>();
+    private final Queue<HttpChannelOverFCGI> idleChannels = new ConcurrentLinkedQueue<>();
+    private final Queue<Promise<Map<String, String>>> valuesPromises = new ConcurrentLinkedQueue<>();
     private final AtomicBoolean closed = new AtomicBoolean();
     private final HttpDestination destination;
     private final Promise<Connection> promise;
     private final Flusher flusher;
+    private final ClientGenerator generator;
     private final Delegate delegate;
     private final ClientParser parser;
-    private HttpChannelOverFCGI channel;
     private RetainableByteBuffer networkBuffer;
     private Object attachment;
+    private volatile int maxMultiplex = 1;
+    private Scheduler.Task healthCheck;
 
     public HttpConnectionOverFCGI(EndPoint endPoint, HttpDestination destination, Promise<Connection> promise)
     {
@@ -79,6 +96,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         this.parser = new ClientParser(new ResponseListener());
         requests.addLast(0);
         HttpClient client = destination.getHttpClient();
+        this.generator = new ClientGenerator(client.getByteBufferPool(), client.isUseOutputDirectByteBuffers());
         this.networkByteBufferPool = client.getByteBufferPool().asRetainableByteBufferPool();
     }
 
@@ -104,12 +122,177 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         return delegate.send(exchange);
     }
 
+    /**
+     * @return the max number of concurrent requests on this connection, as replied
+     * by the backend to the FCGI_GET_VALUES query sent when the connection is opened,
+     * or 1 if the transport is not {@link HttpClientTransportOverFCGI#isMultiplexed() multiplexed}
+     */
+    @Override
+    public int getMaxMultiplex()
+    {
+        return maxMultiplex;
+    }
+
     @Override
     public void onOpen()
     {
         super.onOpen();
         fillInterested();
+        HttpClientTransportOverFCGI transport = getHttpClientTransport();
+        if (transport != null && transport.isMultiplexed())
+        {
+            long timeout = destination.getHttpClient().getConnectTimeout();
+            getValues(VALUES, timeout, new Promise<>()
+            {
+                @Override
+                public void succeeded(Map<String, String> values)
+                {
+                    maxMultiplex = toMaxMultiplex(values);
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Backend values {}, max multiplex {} for {}", values, maxMultiplex, HttpConnectionOverFCGI.this);
+                    opened();
+                }
+
+                @Override
+                public void failed(Throwable x)
+                {
+                    if (isClosed())
+                    {
+                        promise.failed(x);
+                    }
+                    else
+                    {
+                        // The backend did not reply, assume it does not multiplex.
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("No backend values for {}", HttpConnectionOverFCGI.this, x);
+                        opened();
+                    }
+                }
+            });
+        }
+        else
+        {
+            opened();
+        }
+    }
+
+    private void opened()
+    {
         promise.succeeded(this);
+        scheduleHealthCheck();
+    }
+
+    private static int toMaxMultiplex(Map<String, String> values)
+    {
+        if (!"1".equals(values.get(FCGI.Values.MPXS_CONNS)))
+            return 1;
+        try
+        {
+            String maxRequests = values.get(FCGI.Values.MAX_REQS);
+            // The FastCGI request id is an unsigned short.
+            return maxRequests == null ? 0xFF_FF : Math.max(1, Math.min(0xFF_FF, Integer.parseInt(maxRequests.trim())));
+        }
+        catch (NumberFormatException x)
+        {
+            return 1;
+        }
+    }
+
+    private HttpClientTransportOverFCGI getHttpClientTransport()
+    {
+        HttpClientTransport transport = destination.getHttpClient().getTransport();
+        return transport instanceof HttpClientTransportOverFCGI ? (HttpClientTransportOverFCGI)transport : null;
+    }
+
+    /**
+     * <p>Queries the backend for the given values, sending a FCGI_GET_VALUES management frame.</p>
+     *
+     * @param names the names of the values to query, see {@link FCGI.Values}
+     * @param timeout the time in ms to wait for the reply from the backend
+     * @param promise the promise completed with the values replied by the backend
+     */
+    public void getValues(Collection<String> names, long timeout, Promise<Map<String, String>> promise)
+    {
+        ValuesPromise valuesPromise = new ValuesPromise(promise);
+        if (timeout > 0)
+            valuesPromise.task = destination.getHttpClient().getScheduler().schedule(() -> valuesPromise.failed(new TimeoutException("FastCGI values timeout " + timeout + " ms")), timeout, TimeUnit.MILLISECONDS);
+        valuesPromises.offer(valuesPromise);
+        if (isClosed())
+        {
+            // Raced with close(), that may not have seen the promise.
+            failValues(new AsynchronousCloseException());
+            return;
+        }
+        flusher.flush(generator.generateGetValues(names, Callback.from(() -> {}, valuesPromise::failed)));
+    }
+
+    private void failValues(Throwable failure)
+    {
+        while (true)
+        {
+            Promise<Map<String, String>> promise = valuesPromises.poll();
+            if (promise == null)
+                break;
+            promise.failed(failure);
+        }
+    }
+
+    private void scheduleHealthCheck()
+    {
+        HttpClientTransportOverFCGI transport = getHttpClientTransport();
+        if (transport == null)
+            return;
+        long interval = transport.getHealthCheckInterval();
+        if (interval <= 0 || isClosed())
+            return;
+        try (AutoLock l = lock.lock())
+        {
+            healthCheck = destination.getHttpClient().getScheduler().schedule(this::healthCheck, interval, TimeUnit.MILLISECONDS);
+        }
+    }
+
+    private void healthCheck()
+    {
+        HttpClientTransportOverFCGI transport = getHttpClientTransport();
+        if (transport == null || isClosed())
+            return;
+
+        // Only idle connections are checked, as the backend may
+        // not reply until the request in progress is completed.
+        if (!activeChannels.isEmpty())
+        {
+            scheduleHealthCheck();
+            return;
+        }
+
+        getValues(VALUES, transport.getHealthCheckTimeout(), new Promise<>()
+        {
+            @Override
+            public void succeeded(Map<String, String> values)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Health check succeeded for {}", HttpConnectionOverFCGI.this);
+                transport.onHealthCheck(true);
+                scheduleHealthCheck();
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                if (isClosed())
+                    return;
+                if (!activeChannels.isEmpty())
+                {
+                    // A request was sent after the check, so the backend may still be busy.
+                    scheduleHealthCheck();
+                    return;
+                }
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Health check failed for {}", HttpConnectionOverFCGI.this, x);
+                transport.onHealthCheck(false);
+                close(x);
+            }
+        });
     }
 
     @Override
@@ -200,8 +383,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
     {
         // Close explicitly only if we are idle, since the request may still
         // be in progress, otherwise close only if we can fail the responses.
-        HttpChannelOverFCGI channel = this.channel;
-        if (channel == null || channel.getRequest() == 0)
+        if (activeChannels.isEmpty())
             close();
         else
             failAndClose(new EOFException(String.valueOf(getEndPoint())));
@@ -220,24 +402,19 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
 
     protected void release(HttpChannelOverFCGI channel)
     {
-        HttpChannelOverFCGI existing = this.channel;
-        if (existing == channel)
+        if (activeChannels.remove(channel.getRequest(), channel))
         {
             channel.setRequest(0);
             // Recycle only non-failed channels.
             if (channel.isFailed())
-            {
                 channel.destroy();
-                this.channel = null;
-            }
+            else
+                idleChannels.offer(channel);
             destination.release(this);
         }
         else
         {
-            if (existing == null)
-                channel.destroy();
-            else
-                throw new UnsupportedOperationException("FastCGI Multiplex");
+            channel.destroy();
         }
     }
 
@@ -253,6 +430,13 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         {
             getHttpDestination().remove(this);
 
+            try (AutoLock l = lock.lock())
+            {
+                if (healthCheck != null)
+                    healthCheck.cancel();
+            }
+            failValues(failure);
+
             abort(failure);
 
             getEndPoint().shutdownOutput();
@@ -270,6 +454,24 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         return closed.get();
     }
 
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return getEndPoint().getLocalSocketAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return getEndPoint().getRemoteSocketAddress();
+    }
+
+    @Override
+    public String getProtocol()
+    {
+        return "fcgi/1.0";
+    }
+
     @Override
     public void setAttachment(Object obj)
     {
@@ -292,27 +494,33 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
 
     protected void abort(Throwable failure)
     {
-        HttpChannelOverFCGI channel = this.channel;
-        if (channel != null)
+        for (HttpChannelOverFCGI channel : activeChannels.values())
         {
             HttpExchange exchange = channel.getHttpExchange();
             if (exchange != null)
                 exchange.getRequest().abort(failure);
             channel.destroy();
-            this.channel = null;
+        }
+        activeChannels.clear();
+        while (true)
+        {
+            HttpChannelOverFCGI channel = idleChannels.poll();
+            if (channel == null)
+                break;
+            channel.destroy();
         }
     }
 
     private void failAndClose(Throwable failure)
     {
-        HttpChannelOverFCGI channel = this.channel;
-        if (channel != null)
+        boolean result = false;
+        for (HttpChannelOverFCGI channel : activeChannels.values())
         {
-            boolean result = channel.responseFailure(failure);
+            result |= channel.responseFailure(failure);
             channel.destroy();
-            if (result)
-                close(failure);
         }
+        if (result)
+            close(failure);
     }
 
     private int acquireRequest()
@@ -336,9 +544,11 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
 
     protected HttpChannelOverFCGI acquireHttpChannel(int id, Request request)
     {
+        HttpChannelOverFCGI channel = idleChannels.poll();
         if (channel == null)
             channel = newHttpChannel(request);
         channel.setRequest(id);
+        activeChannels.put(id, channel);
         return channel;
     }
 
@@ -357,6 +567,45 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
             getEndPoint().getRemoteSocketAddress());
     }
 
+    private static class ValuesPromise implements Promise<Map<String, String>>
+    {
+        private final AtomicBoolean complete = new AtomicBoolean();
+        private final Promise<Map<String, String>> promise;
+        private volatile Scheduler.Task task;
+
+        private ValuesPromise(Promise<Map<String, String>> promise)
+        {
+            this.promise = promise;
+        }
+
+        @Override
+        public void succeeded(Map<String, String> values)
+        {
+            if (complete.compareAndSet(false, true))
+            {
+                cancel();
+                promise.succeeded(values);
+            }
+        }
+
+        @Override
+        public void failed(Throwable x)
+        {
+            if (complete.compareAndSet(false, true))
+            {
+                cancel();
+                promise.failed(x);
+            }
+        }
+
+        private void cancel()
+        {
+            Scheduler.Task task = this.task;
+            if (task != null)
+                task.cancel();
+        }
+    }
+
     private class Delegate extends HttpConnection
     {
         private Delegate(HttpDestination destination)
@@ -367,8 +616,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         @Override
         protected Iterator<HttpChannel> getHttpChannels()
         {
-            HttpChannel channel = HttpConnectionOverFCGI.this.channel;
-            return channel == null ? Collections.emptyIterator() : Collections.singleton(channel).iterator();
+            return List.<HttpChannel>copyOf(activeChannels.values()).iterator();
         }
 
         @Override
@@ -401,6 +649,18 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
             return HttpConnectionOverFCGI.this.isClosed();
         }
 
+        @Override
+        public SocketAddress getLocalSocketAddress()
+        {
+            return HttpConnectionOverFCGI.this.getLocalSocketAddress();
+        }
+
+        @Override
+        public SocketAddress getRemoteSocketAddress()
+        {
+            return HttpConnectionOverFCGI.this.getRemoteSocketAddress();
+        }
+
         @Override
         public String toString()
         {
@@ -410,10 +670,20 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
 
     private class ResponseListener implements ClientParser.Listener
     {
+        @Override
+        public void onValues(Map<String, String> values)
+        {
+            Promise<Map<String, String>> promise = valuesPromises.poll();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Values {} for {} on {}", values, promise, HttpConnectionOverFCGI.this);
+            if (promise != null)
+                promise.succeeded(values);
+        }
+
         @Override
         public void onBegin(int request, int code, String reason)
         {
-            HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+            HttpChannelOverFCGI channel = activeChannels.get(request);
             if (channel != null)
                 channel.responseBegin(code, reason);
             else
@@ -423,7 +693,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         @Override
         public void onHeader(int request, HttpField field)
         {
-            HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+            HttpChannelOverFCGI channel = activeChannels.get(request);
             if (channel != null)
                 channel.responseHeader(field);
             else
@@ -433,7 +703,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         @Override
         public boolean onHeaders(int request)
         {
-            HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+            HttpChannelOverFCGI channel = activeChannels.get(request);
             if (channel != null)
                 return !channel.responseHeaders();
             noChannel(request);
@@ -447,7 +717,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
             {
                 case STD_OUT:
                 {
-                    HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+                    HttpChannelOverFCGI channel = activeChannels.get(request);
                     if (channel != null)
                     {
                         networkBuffer.retain();
@@ -475,7 +745,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         @Override
         public void onEnd(int request)
         {
-            HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+            HttpChannelOverFCGI channel = activeChannels.get(request);
             if (channel != null)
             {
                 if (channel.responseSuccess())
@@ -490,7 +760,7 @@ public class HttpConnectionOverFCGI extends AbstractConnection implements IConne
         @Override
         public void onFailure(int request, Throwable failure)
         {
-            HttpChannelOverFCGI channel = HttpConnectionOverFCGI.this.channel;
+            HttpChannelOverFCGI channel = activeChannels.get(request);
             if (channel != null)
             {
                 if (channel.responseFailure(failure))
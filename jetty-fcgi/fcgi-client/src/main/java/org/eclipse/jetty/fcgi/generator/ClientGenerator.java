@@ -17,7 +17,10 @@ import java.nio.ByteBuffer;
 import java.nio.charset.Charset;
 import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
+import java.util.Collection;
+import java.util.LinkedHashMap;
 import java.util.List;
+import java.util.Map;
 
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.http.HttpField;
@@ -144,23 +147,25 @@ public class ClientGenerator extends Generator
         return result;
     }
 
-    private int putParamLength(ByteBuffer buffer, int length)
-    {
-        int result = bytesForLength(length);
-        if (result == 4)
-            buffer.putInt(length | 0x80_00_00_00);
-        else
-            buffer.put((byte)length);
-        return result;
-    }
-
-    private int bytesForLength(int length)
+    public Result generateRequestContent(int request, ByteBuffer content, boolean lastContent, Callback callback)
     {
-        return length > 127 ? 4 : 1;
+        return generateContent(request, content, false, lastContent, callback, FCGI.FrameType.STDIN);
     }
 
-    public Result generateRequestContent(int request, ByteBuffer content, boolean lastContent, Callback callback)
+    /**
+     * <p>Generates a FCGI_GET_VALUES management frame that queries the given values.</p>
+     *
+     * @param names the names of the values to query, see {@link FCGI.Values}
+     * @param callback the callback to notify when the frame has been written
+     * @return the generated frame
+     */
+    public Result generateGetValues(Collection<String> names, Callback callback)
     {
-        return generateContent(request, content, false, lastContent, callback, FCGI.FrameType.STDIN);
+        Map<String, String> values = new LinkedHashMap<>();
+        for (String name : names)
+        {
+            values.put(name, "");
+        }
+        return generateValues(FCGI.FrameType.GET_VALUES, values, callback);
     }
 }
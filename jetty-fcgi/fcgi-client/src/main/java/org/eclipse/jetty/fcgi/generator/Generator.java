@@ -14,8 +14,11 @@
 package org.eclipse.jetty.fcgi.generator;
 
 import java.nio.ByteBuffer;
+import java.nio.charset.Charset;
+import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
 import java.util.List;
+import java.util.Map;
 
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.io.ByteBufferPool;
@@ -87,6 +90,70 @@ public class Generator
         return result;
     }
 
+    /**
+     * <p>Generates a management frame, with request id 0, containing the given name/value pairs.</p>
+     *
+     * @param frameType either {@link FCGI.FrameType#GET_VALUES} or {@link FCGI.FrameType#GET_VALUES_RESULT}
+     * @param values the name/value pairs
+     * @param callback the callback to notify when the frame has been written
+     * @return the generated frame
+     */
+    protected Result generateValues(FCGI.FrameType frameType, Map<String, String> values, Callback callback)
+    {
+        Charset utf8 = StandardCharsets.UTF_8;
+        List<byte[]> bytes = new ArrayList<>(values.size() * 2);
+        int length = 0;
+        for (Map.Entry<String, String> entry : values.entrySet())
+        {
+            byte[] nameBytes = entry.getKey().getBytes(utf8);
+            byte[] valueBytes = entry.getValue().getBytes(utf8);
+            bytes.add(nameBytes);
+            bytes.add(valueBytes);
+            length += bytesForLength(nameBytes.length) + bytesForLength(valueBytes.length) + nameBytes.length + valueBytes.length;
+        }
+        if (length > MAX_CONTENT_LENGTH)
+            throw new IllegalArgumentException("Values length " + length + " exceeds max length " + MAX_CONTENT_LENGTH);
+
+        ByteBuffer buffer = acquire(8 + length);
+        BufferUtil.clearToFill(buffer);
+        Result result = new Result(byteBufferPool, callback);
+        result = result.append(buffer, true);
+
+        // Generate the frame header, management frames have request id 0.
+        buffer.put((byte)0x01);
+        buffer.put((byte)frameType.code);
+        buffer.putShort((short)0);
+        buffer.putShort((short)length);
+        buffer.putShort((short)0);
+        for (int i = 0; i < bytes.size(); i += 2)
+        {
+            byte[] nameBytes = bytes.get(i);
+            byte[] valueBytes = bytes.get(i + 1);
+            putParamLength(buffer, nameBytes.length);
+            putParamLength(buffer, valueBytes.length);
+            buffer.put(nameBytes);
+            buffer.put(valueBytes);
+        }
+        BufferUtil.flipToFlush(buffer, 0);
+
+        return result;
+    }
+
+    int putParamLength(ByteBuffer buffer, int length)
+    {
+        int result = bytesForLength(length);
+        if (result == 4)
+            buffer.putInt(length | 0x80_00_00_00);
+        else
+            buffer.put((byte)length);
+        return result;
+    }
+
+    int bytesForLength(int length)
+    {
+        return length > 127 ? 4 : 1;
+    }
+
     // TODO: rewrite this class in light of ByteBufferPool.Lease.
     public static class Result implements Callback
     {
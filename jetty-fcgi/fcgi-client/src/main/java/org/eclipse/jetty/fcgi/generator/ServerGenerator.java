@@ -18,6 +18,7 @@ import java.nio.charset.Charset;
 import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
 import java.util.List;
+import java.util.Map;
 
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.http.HttpField;
@@ -117,6 +118,18 @@ public class ServerGenerator extends Generator
         }
     }
 
+    /**
+     * <p>Generates a FCGI_GET_VALUES_RESULT management frame with the given values.</p>
+     *
+     * @param values the values, see {@link FCGI.Values}
+     * @param callback the callback to notify when the frame has been written
+     * @return the generated frame
+     */
+    public Result generateGetValuesResult(Map<String, String> values, Callback callback)
+    {
+        return generateValues(FCGI.FrameType.GET_VALUES_RESULT, values, callback);
+    }
+
     private ByteBuffer generateEndRequest(int request, boolean aborted)
     {
         request &= 0xFF_FF;
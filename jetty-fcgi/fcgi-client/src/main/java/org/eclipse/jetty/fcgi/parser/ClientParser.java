@@ -15,6 +15,7 @@ package org.eclipse.jetty.fcgi.parser;
 
 import java.nio.ByteBuffer;
 import java.util.EnumMap;
+import java.util.Map;
 
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.http.HttpField;
@@ -30,6 +31,7 @@ public class ClientParser extends Parser
         StreamContentParser stdErrParser = new StreamContentParser(headerParser, FCGI.StreamType.STD_ERR, listener);
         contentParsers.put(FCGI.FrameType.STDERR, stdErrParser);
         contentParsers.put(FCGI.FrameType.END_REQUEST, new EndRequestContentParser(headerParser, new EndRequestListener(listener, stdOutParser, stdErrParser)));
+        contentParsers.put(FCGI.FrameType.GET_VALUES_RESULT, new ValuesContentParser(headerParser, listener::onValues));
     }
 
     @Override
@@ -42,12 +44,22 @@ public class ClientParser extends Parser
     {
         public void onBegin(int request, int code, String reason);
 
+        /**
+         * @param values the values of a FCGI_GET_VALUES_RESULT management frame
+         */
+        public void onValues(Map<String, String> values);
+
         public static class Adapter extends Parser.Listener.Adapter implements Listener
         {
             @Override
             public void onBegin(int request, int code, String reason)
             {
             }
+
+            @Override
+            public void onValues(Map<String, String> values)
+            {
+            }
         }
     }
 
@@ -68,6 +80,12 @@ public class ClientParser extends Parser
             listener.onBegin(request, code, reason);
         }
 
+        @Override
+        public void onValues(Map<String, String> values)
+        {
+            listener.onValues(values);
+        }
+
         @Override
         public void onHeader(int request, HttpField field)
         {
@@ -14,6 +14,7 @@
 package org.eclipse.jetty.fcgi.parser;
 
 import java.util.EnumMap;
+import java.util.Set;
 
 import org.eclipse.jetty.fcgi.FCGI;
 
@@ -26,6 +27,7 @@ public class ServerParser extends Parser
         contentParsers.put(FCGI.FrameType.BEGIN_REQUEST, new BeginRequestContentParser(headerParser, listener));
         contentParsers.put(FCGI.FrameType.PARAMS, new ParamsContentParser(headerParser, listener));
         contentParsers.put(FCGI.FrameType.STDIN, new StreamContentParser(headerParser, FCGI.StreamType.STD_IN, listener));
+        contentParsers.put(FCGI.FrameType.GET_VALUES, new ValuesContentParser(headerParser, values -> listener.onGetValues(values.keySet())));
     }
 
     @Override
@@ -38,12 +40,22 @@ public class ServerParser extends Parser
     {
         public void onStart(int request, FCGI.Role role, int flags);
 
+        /**
+         * @param names the names of the values queried by a FCGI_GET_VALUES management frame
+         */
+        public void onGetValues(Set<String> names);
+
         public static class Adapter extends Parser.Listener.Adapter implements Listener
         {
             @Override
             public void onStart(int request, FCGI.Role role, int flags)
             {
             }
+
+            @Override
+            public void onGetValues(Set<String> names)
+            {
+            }
         }
     }
 }
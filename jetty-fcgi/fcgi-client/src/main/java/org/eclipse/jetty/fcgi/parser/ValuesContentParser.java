@@ -0,0 +1,73 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.fcgi.parser;
+
+import java.nio.ByteBuffer;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.function.Consumer;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Parser for the GET_VALUES and GET_VALUES_RESULT frame content.</p>
+ * <p>The content has the same format of the PARAMS frame content, but
+ * it is contained in a single frame, rather than in a stream of frames.</p>
+ */
+public class ValuesContentParser extends ParamsContentParser
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ValuesContentParser.class);
+
+    private final Consumer<Map<String, String>> listener;
+    private Map<String, String> values = new LinkedHashMap<>();
+
+    public ValuesContentParser(HeaderParser headerParser, Consumer<Map<String, String>> listener)
+    {
+        super(headerParser, new ServerParser.Listener.Adapter());
+        this.listener = listener;
+    }
+
+    @Override
+    public Result parse(ByteBuffer buffer)
+    {
+        Result result = super.parse(buffer);
+        if (result == Result.COMPLETE)
+            onParams();
+        return result;
+    }
+
+    @Override
+    protected void onParam(String name, String value)
+    {
+        values.put(name, value);
+    }
+
+    @Override
+    protected boolean onParams()
+    {
+        Map<String, String> result = values;
+        values = new LinkedHashMap<>();
+        try
+        {
+            listener.accept(result);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Exception while invoking listener {}", listener, x);
+        }
+        return false;
+    }
+}
@@ -14,7 +14,10 @@
 package org.eclipse.jetty.fcgi.generator;
 
 import java.nio.ByteBuffer;
+import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.List;
+import java.util.Set;
 import java.util.concurrent.atomic.AtomicInteger;
 
 import org.eclipse.jetty.fcgi.FCGI;
@@ -192,4 +195,33 @@ public class ClientGeneratorTest
             assertFalse(buffer.hasRemaining());
         }
     }
+
+    @Test
+    public void testGenerateGetValues() throws Exception
+    {
+        List<String> names = List.of(FCGI.Values.MAX_CONNS, FCGI.Values.MAX_REQS, FCGI.Values.MPXS_CONNS);
+
+        ByteBufferPool byteBufferPool = new MappedByteBufferPool();
+        ClientGenerator generator = new ClientGenerator(byteBufferPool);
+        Generator.Result result = generator.generateGetValues(names, null);
+
+        List<Set<String>> queries = new ArrayList<>();
+        ServerParser parser = new ServerParser(new ServerParser.Listener.Adapter()
+        {
+            @Override
+            public void onGetValues(Set<String> names)
+            {
+                queries.add(names);
+            }
+        });
+
+        for (ByteBuffer buffer : result.getByteBuffers())
+        {
+            parser.parse(buffer);
+            assertFalse(buffer.hasRemaining());
+        }
+
+        assertEquals(1, queries.size());
+        assertEquals(names, new ArrayList<>(queries.get(0)));
+    }
 }
@@ -14,7 +14,11 @@
 package org.eclipse.jetty.fcgi.parser;
 
 import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
 import java.util.Locale;
+import java.util.Map;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicInteger;
 
@@ -254,4 +258,36 @@ public class ClientParserTest
 
         assertTrue(verifier.get());
     }
+
+    @Test
+    public void testParseGetValuesResult() throws Exception
+    {
+        Map<String, String> values = new LinkedHashMap<>();
+        values.put(FCGI.Values.MAX_REQS, "10");
+        values.put(FCGI.Values.MPXS_CONNS, "1");
+        // Exercise the 4 bytes length encoding.
+        values.put("X_LONG", "x".repeat(200));
+
+        ByteBufferPool byteBufferPool = new MappedByteBufferPool();
+        ServerGenerator generator = new ServerGenerator(byteBufferPool);
+        Generator.Result result = generator.generateGetValuesResult(values, null);
+
+        List<Map<String, String>> results = new ArrayList<>();
+        ClientParser parser = new ClientParser(new ClientParser.Listener.Adapter()
+        {
+            @Override
+            public void onValues(Map<String, String> values)
+            {
+                results.add(values);
+            }
+        });
+
+        for (ByteBuffer buffer : result.getByteBuffers())
+        {
+            parser.parse(buffer);
+            assertFalse(buffer.hasRemaining());
+        }
+
+        assertEquals(List.of(values), results);
+    }
 }
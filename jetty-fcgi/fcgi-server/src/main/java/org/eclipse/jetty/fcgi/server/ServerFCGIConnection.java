@@ -14,9 +14,13 @@
 package org.eclipse.jetty.fcgi.server;
 
 import java.nio.ByteBuffer;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Set;
 
 import org.eclipse.jetty.fcgi.FCGI;
 import org.eclipse.jetty.fcgi.generator.Flusher;
+import org.eclipse.jetty.fcgi.generator.ServerGenerator;
 import org.eclipse.jetty.fcgi.parser.ServerParser;
 import org.eclipse.jetty.http.BadMessageException;
 import org.eclipse.jetty.http.HttpField;
@@ -28,6 +32,7 @@ import org.eclipse.jetty.io.RetainableByteBufferPool;
 import org.eclipse.jetty.server.Connector;
 import org.eclipse.jetty.server.HttpConfiguration;
 import org.eclipse.jetty.server.HttpInput;
+import org.eclipse.jetty.util.Callback;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -221,6 +226,24 @@ public class ServerFCGIConnection extends AbstractConnection
                 LOG.debug("Request {} start on {}", request, channel);
         }
 
+        @Override
+        public void onGetValues(Set<String> names)
+        {
+            // This implementation does not support multiplexing.
+            Map<String, String> values = new LinkedHashMap<>();
+            for (String name : names)
+            {
+                if (FCGI.Values.MAX_REQS.equals(name))
+                    values.put(name, "1");
+                else if (FCGI.Values.MPXS_CONNS.equals(name))
+                    values.put(name, "0");
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Values {} for {} on {}", values, names, ServerFCGIConnection.this);
+            ServerGenerator generator = new ServerGenerator(connector.getByteBufferPool(), isUseOutputDirectByteBuffers(), sendStatus200);
+            flusher.flush(generator.generateGetValuesResult(values, Callback.NOOP));
+        }
+
         @Override
         public void onHeader(int request, HttpField field)
         {
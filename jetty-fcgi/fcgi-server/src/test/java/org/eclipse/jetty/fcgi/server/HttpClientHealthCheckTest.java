@@ -0,0 +1,122 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.fcgi.server;
+
+import java.net.InetSocketAddress;
+import java.nio.channels.ServerSocketChannel;
+import java.util.concurrent.TimeUnit;
+import java.util.function.BooleanSupplier;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.Connection;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Destination;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.fcgi.client.http.HttpClientTransportOverFCGI;
+import org.eclipse.jetty.fcgi.client.http.HttpConnectionOverFCGI;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.FuturePromise;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class HttpClientHealthCheckTest extends AbstractHttpClientServerTest
+{
+    @Test
+    public void testHealthCheckKeepsHealthyConnection() throws Exception
+    {
+        start(new EmptyServerHandler());
+        HttpClientTransportOverFCGI transport = (HttpClientTransportOverFCGI)client.getTransport();
+        transport.setHealthCheckInterval(100);
+
+        Request request = client.newRequest("localhost", connector.getLocalPort()).scheme(scheme);
+        Destination destination = client.resolveDestination(request);
+        FuturePromise<Connection> futureConnection = new FuturePromise<>();
+        destination.newConnection(futureConnection);
+        try (Connection connection = futureConnection.get(5, TimeUnit.SECONDS))
+        {
+            await(() -> transport.getHealthChecks() >= 2);
+            assertEquals(0, transport.getHealthCheckFailures());
+            assertFalse(connection.isClosed());
+
+            ContentResponse response = request.timeout(5, TimeUnit.SECONDS).send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+        }
+    }
+
+    @Test
+    public void testHealthCheckClosesUnresponsiveConnection() throws Exception
+    {
+        // A backend that accepts connections but never replies.
+        try (ServerSocketChannel backend = ServerSocketChannel.open())
+        {
+            backend.bind(new InetSocketAddress("localhost", 0));
+            int port = backend.socket().getLocalPort();
+
+            HttpClientTransportOverFCGI transport = new HttpClientTransportOverFCGI(1, "");
+            transport.setHealthCheckInterval(100);
+            transport.setHealthCheckTimeout(100);
+            client = new HttpClient(transport);
+            client.start();
+
+            Destination destination = client.resolveDestination(client.newRequest("localhost", port));
+            FuturePromise<Connection> futureConnection = new FuturePromise<>();
+            destination.newConnection(futureConnection);
+            Connection connection = futureConnection.get(5, TimeUnit.SECONDS);
+
+            await(connection::isClosed);
+            assertEquals(1, transport.getHealthCheckFailures());
+            assertEquals(0, (int)transport.getQueuedRequestsPerBackend().get("localhost:" + port));
+        }
+    }
+
+    @Test
+    public void testMultiplexedWithNonMultiplexingBackend() throws Exception
+    {
+        start(new EmptyServerHandler());
+        HttpClientTransportOverFCGI transport = (HttpClientTransportOverFCGI)client.getTransport();
+        transport.setMultiplexed(true);
+
+        Request request = client.newRequest("localhost", connector.getLocalPort()).scheme(scheme);
+        Destination destination = client.resolveDestination(request);
+        FuturePromise<Connection> futureConnection = new FuturePromise<>();
+        destination.newConnection(futureConnection);
+        try (Connection connection = futureConnection.get(5, TimeUnit.SECONDS))
+        {
+            // The server replies FCGI_MPXS_CONNS=0.
+            assertEquals(1, ((HttpConnectionOverFCGI)connection).getMaxMultiplex());
+        }
+
+        for (int i = 0; i < 2; ++i)
+        {
+            ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+                .scheme(scheme)
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+        }
+    }
+
+    private static void await(BooleanSupplier condition) throws InterruptedException
+    {
+        long end = System.nanoTime() + TimeUnit.SECONDS.toNanos(5);
+        while (!condition.getAsBoolean())
+        {
+            assertTrue(System.nanoTime() < end, "Condition not met");
+            Thread.sleep(10);
+        }
+    }
+}
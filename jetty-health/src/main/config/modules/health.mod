@@ -0,0 +1,37 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Serves the liveness (/livez), readiness (/readyz) and startup (/startupz)
+health probes of the server as JSON, checking the thread pool saturation
+and the connectors status.
+The readiness probe fails as soon as a graceful shutdown is initiated,
+see the jetty.server.stopTimeout property of the server module.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-health.xml
+
+[lib]
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/jetty-health-${jetty.version}.jar
+
+[ini-template]
+## The path of the liveness probe.
+# jetty.health.livenessPath=/livez
+
+## The path of the readiness probe.
+# jetty.health.readinessPath=/readyz
+
+## The path of the startup probe.
+# jetty.health.startupPath=/startupz
+
+## The thread pool utilization rate, between 0 and 1, above which readiness fails.
+# jetty.health.threadPool.maxUtilizationRate=1.0
+
+## The thread pool queue size above which readiness fails, or -1 for no limit.
+# jetty.health.threadPool.maxQueueSize=-1
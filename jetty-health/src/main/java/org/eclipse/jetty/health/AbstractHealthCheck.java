@@ -0,0 +1,68 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.Collections;
+import java.util.EnumSet;
+import java.util.Objects;
+import java.util.Set;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+
+/**
+ * <p>A base {@link HealthCheck} with a configurable name and set of probes.</p>
+ */
+@ManagedObject("Health check")
+public abstract class AbstractHealthCheck implements HealthCheck
+{
+    private final String name;
+    private volatile Set<Probe> probes;
+
+    protected AbstractHealthCheck(String name, Set<Probe> probes)
+    {
+        this.name = Objects.requireNonNull(name);
+        setProbes(probes);
+    }
+
+    @Override
+    @ManagedAttribute("The health check name")
+    public String getName()
+    {
+        return name;
+    }
+
+    @Override
+    @ManagedAttribute("The probes the health check contributes to")
+    public Set<Probe> getProbes()
+    {
+        return probes;
+    }
+
+    /**
+     * @param probes the probes this health check contributes to
+     */
+    public void setProbes(Set<Probe> probes)
+    {
+        if (probes.isEmpty())
+            throw new IllegalArgumentException("No probes");
+        this.probes = Collections.unmodifiableSet(EnumSet.copyOf(probes));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), getName(), getProbes());
+    }
+}
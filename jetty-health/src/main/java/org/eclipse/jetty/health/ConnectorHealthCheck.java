@@ -0,0 +1,66 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.EnumSet;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.NetworkConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link HealthCheck} that fails when a {@link Connector} of the {@link Server}
+ * is not running, or when a {@link NetworkConnector} is not open, for example
+ * because it failed to bind or because it has been shut down.</p>
+ */
+@ManagedObject("Connector status health check")
+public class ConnectorHealthCheck extends AbstractHealthCheck
+{
+    private final Server server;
+
+    public ConnectorHealthCheck(@Name("server") Server server)
+    {
+        super("connectors", EnumSet.of(Probe.READINESS, Probe.STARTUP));
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public Result check()
+    {
+        Connector[] connectors = server.getConnectors();
+        String reason = connectors.length == 0 ? "no connectors" : null;
+        Map<String, Object> statuses = new LinkedHashMap<>();
+        for (Connector connector : connectors)
+        {
+            String name = connector.getName() == null ? connector.toString() : connector.getName();
+            boolean up = connector.isRunning();
+            if (up && connector instanceof NetworkConnector)
+                up = ((NetworkConnector)connector).isOpen();
+            if (!up && reason == null)
+                reason = "connector " + name + " down";
+            statuses.put(name, up ? "UP" : "DOWN");
+        }
+        Result result = reason == null ? Result.up() : Result.down(reason);
+        for (Map.Entry<String, Object> entry : statuses.entrySet())
+        {
+            result = result.with(entry.getKey(), entry.getValue());
+        }
+        return result;
+    }
+}
@@ -0,0 +1,166 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.Collections;
+import java.util.EnumSet;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.function.BooleanSupplier;
+
+/**
+ * <p>A named check of the health of a component, exposed by {@link HealthCheckHandler}.</p>
+ * <p>Each health check contributes to one or more {@link Probe}s; a probe is healthy
+ * only if all its checks are {@link Result#isUp() up}.</p>
+ * <p>Health checks may be added directly to a {@link HealthCheckHandler}, or added as
+ * beans to the {@link org.eclipse.jetty.server.Server}, for example from a Jetty XML file.</p>
+ *
+ * @see AbstractHealthCheck
+ */
+public interface HealthCheck
+{
+    /**
+     * @return the name of this health check
+     */
+    String getName();
+
+    /**
+     * @return the probes this health check contributes to
+     */
+    default Set<Probe> getProbes()
+    {
+        return EnumSet.of(Probe.READINESS);
+    }
+
+    /**
+     * <p>Checks the health of the component.</p>
+     * <p>This method is invoked for every probe request, so it should be fast;
+     * throwing an exception is equivalent to returning a {@link Result#down(String) down} result.</p>
+     *
+     * @return the result of the check
+     * @throws Exception if the check could not be performed
+     */
+    Result check() throws Exception;
+
+    /**
+     * <p>Creates a custom health check from a {@link BooleanSupplier}.</p>
+     *
+     * @param name the name of the health check
+     * @param probes the probes the health check contributes to
+     * @param check the supplier returning whether the component is healthy
+     * @return a new health check
+     */
+    static HealthCheck from(String name, Set<Probe> probes, BooleanSupplier check)
+    {
+        Objects.requireNonNull(check);
+        return new AbstractHealthCheck(name, probes)
+        {
+            @Override
+            public Result check()
+            {
+                return check.getAsBoolean() ? Result.up() : Result.down(null);
+            }
+        };
+    }
+
+    /**
+     * The probes exposed by {@link HealthCheckHandler}.
+     */
+    enum Probe
+    {
+        /**
+         * Whether the server is alive; failing liveness typically causes a restart.
+         */
+        LIVENESS,
+        /**
+         * Whether the server is ready to receive traffic.
+         */
+        READINESS,
+        /**
+         * Whether the server has completed its startup.
+         */
+        STARTUP
+    }
+
+    /**
+     * The immutable result of a {@link HealthCheck}.
+     */
+    final class Result
+    {
+        private static final Result UP = new Result(true, null, Collections.emptyMap());
+
+        private final boolean up;
+        private final String reason;
+        private final Map<String, Object> details;
+
+        private Result(boolean up, String reason, Map<String, Object> details)
+        {
+            this.up = up;
+            this.reason = reason;
+            this.details = details;
+        }
+
+        /**
+         * @return a healthy result
+         */
+        public static Result up()
+        {
+            return UP;
+        }
+
+        /**
+         * @param reason the reason of the failure, or null
+         * @return an unhealthy result
+         */
+        public static Result down(String reason)
+        {
+            return new Result(false, reason, Collections.emptyMap());
+        }
+
+        /**
+         * @param name the detail name
+         * @param value the detail value, for example a number or a string
+         * @return a copy of this result with the given detail added
+         */
+        public Result with(String name, Object value)
+        {
+            Map<String, Object> details = new LinkedHashMap<>(this.details);
+            details.put(Objects.requireNonNull(name), value);
+            return new Result(up, reason, Collections.unmodifiableMap(details));
+        }
+
+        public boolean isUp()
+        {
+            return up;
+        }
+
+        public String getReason()
+        {
+            return reason;
+        }
+
+        public Map<String, Object> getDetails()
+        {
+            return details;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,reason=%s,%s]", getClass().getSimpleName(), hashCode(), up ? "UP" : "DOWN", reason, details);
+        }
+    }
+}
@@ -0,0 +1,257 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CopyOnWriteArrayList;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.Graceful;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that serves the health probes of the server,
+ * by default at {@code /livez}, {@code /readyz} and {@code /startupz},
+ * passing all other requests to the wrapped handler.</p>
+ * <p>Each probe runs the {@link HealthCheck}s that contribute to it, that are either
+ * {@link #addHealthCheck(HealthCheck) added} to this handler or added as beans to the
+ * {@link Server}, and responds with a JSON document describing the result of each
+ * check, with status {@code 200} if all the checks are up, or {@code 503} otherwise.</p>
+ * <p>In addition to the health checks:</p>
+ * <ul>
+ * <li>the readiness and startup probes fail until the server is started;</li>
+ * <li>the readiness probe fails as soon as a graceful shutdown is initiated,
+ * see {@link Server#setStopTimeout(long)}, so that load balancers stop sending
+ * traffic to the server while it drains the existing requests.</li>
+ * </ul>
+ */
+@ManagedObject("Health check handler")
+public class HealthCheckHandler extends HandlerWrapper implements Graceful
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HealthCheckHandler.class);
+
+    private final List<HealthCheck> healthChecks = new CopyOnWriteArrayList<>();
+    private String livenessPath = "/livez";
+    private String readinessPath = "/readyz";
+    private String startupPath = "/startupz";
+    private volatile boolean shutdown;
+
+    @ManagedAttribute("The path of the liveness probe")
+    public String getLivenessPath()
+    {
+        return livenessPath;
+    }
+
+    public void setLivenessPath(String livenessPath)
+    {
+        this.livenessPath = livenessPath;
+    }
+
+    @ManagedAttribute("The path of the readiness probe")
+    public String getReadinessPath()
+    {
+        return readinessPath;
+    }
+
+    public void setReadinessPath(String readinessPath)
+    {
+        this.readinessPath = readinessPath;
+    }
+
+    @ManagedAttribute("The path of the startup probe")
+    public String getStartupPath()
+    {
+        return startupPath;
+    }
+
+    public void setStartupPath(String startupPath)
+    {
+        this.startupPath = startupPath;
+    }
+
+    /**
+     * @param healthCheck the health check to add
+     */
+    public void addHealthCheck(HealthCheck healthCheck)
+    {
+        healthChecks.add(Objects.requireNonNull(healthCheck));
+        addBean(healthCheck);
+    }
+
+    /**
+     * @param healthCheck the health check to remove
+     * @return whether the health check was removed
+     */
+    public boolean removeHealthCheck(HealthCheck healthCheck)
+    {
+        removeBean(healthCheck);
+        return healthChecks.remove(healthCheck);
+    }
+
+    /**
+     * @return the health checks added to this handler, followed by those added as beans to the server
+     */
+    public List<HealthCheck> getHealthChecks()
+    {
+        Set<HealthCheck> result = new LinkedHashSet<>(healthChecks);
+        Server server = getServer();
+        if (server != null)
+            result.addAll(server.getBeans(HealthCheck.class));
+        return new ArrayList<>(result);
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        shutdown = false;
+        super.doStart();
+    }
+
+    @Override
+    public CompletableFuture<Void> shutdown()
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Shutdown, readiness failing {}", this);
+        shutdown = true;
+        return CompletableFuture.completedFuture(null);
+    }
+
+    @Override
+    public boolean isShutdown()
+    {
+        return shutdown;
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HealthCheck.Probe probe = getProbe(target);
+        if (probe == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setHandled(true);
+        String method = request.getMethod();
+        boolean head = HttpMethod.HEAD.is(method);
+        if (!head && !HttpMethod.GET.is(method))
+        {
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+
+        Map<String, Object> result = probe(probe);
+        byte[] content = new JSON().toJSON(result).getBytes(StandardCharsets.UTF_8);
+        response.setStatus("UP".equals(result.get("status")) ? HttpStatus.OK_200 : HttpStatus.SERVICE_UNAVAILABLE_503);
+        response.setContentType(MimeTypes.Type.APPLICATION_JSON_UTF_8.asString());
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-store");
+        response.setContentLength(content.length);
+        if (!head)
+            response.getOutputStream().write(content);
+    }
+
+    private HealthCheck.Probe getProbe(String target)
+    {
+        if (target.equals(livenessPath))
+            return HealthCheck.Probe.LIVENESS;
+        if (target.equals(readinessPath))
+            return HealthCheck.Probe.READINESS;
+        if (target.equals(startupPath))
+            return HealthCheck.Probe.STARTUP;
+        return null;
+    }
+
+    /**
+     * <p>Runs the health checks of the given probe.</p>
+     *
+     * @param probe the probe to run
+     * @return the result of the probe, as a JSON object
+     */
+    protected Map<String, Object> probe(HealthCheck.Probe probe)
+    {
+        String reason = null;
+        if (probe == HealthCheck.Probe.READINESS && isShutdown())
+            reason = "shutting down";
+        else if (probe != HealthCheck.Probe.LIVENESS && (getServer() == null || !getServer().isStarted()))
+            reason = "starting";
+
+        Map<String, Object> checks = new LinkedHashMap<>();
+        for (HealthCheck healthCheck : getHealthChecks())
+        {
+            if (!healthCheck.getProbes().contains(probe))
+                continue;
+            HealthCheck.Result result;
+            try
+            {
+                result = healthCheck.check();
+                if (result == null)
+                    result = HealthCheck.Result.down("no result");
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Health check failed {}", healthCheck, x);
+                result = HealthCheck.Result.down(x.toString());
+            }
+            if (!result.isUp() && reason == null)
+                reason = "check " + healthCheck.getName() + " down";
+
+            Map<String, Object> check = new LinkedHashMap<>();
+            check.put("status", result.isUp() ? "UP" : "DOWN");
+            if (result.getReason() != null)
+                check.put("reason", result.getReason());
+            if (!result.getDetails().isEmpty())
+                check.put("details", result.getDetails());
+            checks.put(healthCheck.getName(), check);
+        }
+
+        Map<String, Object> json = new LinkedHashMap<>();
+        json.put("status", reason == null ? "UP" : "DOWN");
+        if (reason != null)
+            json.put("reason", reason);
+        json.put("checks", checks);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Probe {} {}", probe, json);
+        return json;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[shutdown=%b,checks=%d]", getClass().getSimpleName(), hashCode(), shutdown, healthChecks.size());
+    }
+}
@@ -0,0 +1,67 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.EnumSet;
+import java.util.Objects;
+
+import org.eclipse.jetty.server.session.SessionDataStore;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link HealthCheck} that verifies the connectivity of a {@link SessionDataStore},
+ * for example a JDBC or a remote cache store, by checking the existence of a
+ * {@link #getSessionId() session id} that is not expected to exist.</p>
+ * <p>The check performs a round trip to the store for every probe request, so probes
+ * should not be too frequent when the store is remote.</p>
+ */
+@ManagedObject("Session data store connectivity health check")
+public class SessionDataStoreHealthCheck extends AbstractHealthCheck
+{
+    private final SessionDataStore sessionDataStore;
+    private String sessionId = "jetty-health-check";
+
+    public SessionDataStoreHealthCheck(@Name("name") String name, @Name("sessionDataStore") SessionDataStore sessionDataStore)
+    {
+        super(name, EnumSet.of(Probe.READINESS));
+        this.sessionDataStore = Objects.requireNonNull(sessionDataStore);
+    }
+
+    public SessionDataStore getSessionDataStore()
+    {
+        return sessionDataStore;
+    }
+
+    @ManagedAttribute("The session id used to check the store")
+    public String getSessionId()
+    {
+        return sessionId;
+    }
+
+    public void setSessionId(String sessionId)
+    {
+        this.sessionId = Objects.requireNonNull(sessionId);
+    }
+
+    @Override
+    public Result check() throws Exception
+    {
+        if (!sessionDataStore.isStarted())
+            return Result.down("session data store not started");
+        sessionDataStore.exists(sessionId);
+        return Result.up();
+    }
+}
@@ -0,0 +1,111 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.EnumSet;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.eclipse.jetty.util.thread.ThreadPool;
+
+/**
+ * <p>A {@link HealthCheck} that fails readiness when a {@link ThreadPool} is saturated.</p>
+ * <p>The thread pool is saturated when it is {@link ThreadPool#isLowOnThreads() low on threads}
+ * or, for a {@link QueuedThreadPool}, when its {@link QueuedThreadPool#getUtilizationRate()
+ * utilization rate} exceeds {@link #getMaxUtilizationRate()}, or its queue size exceeds
+ * {@link #getMaxQueueSize()}.</p>
+ */
+@ManagedObject("Thread pool saturation health check")
+public class ThreadPoolHealthCheck extends AbstractHealthCheck
+{
+    private final ThreadPool threadPool;
+    private double maxUtilizationRate = 1.0;
+    private int maxQueueSize = -1;
+
+    public ThreadPoolHealthCheck(@Name("threadPool") ThreadPool threadPool)
+    {
+        super("threadPool", EnumSet.of(Probe.READINESS));
+        this.threadPool = Objects.requireNonNull(threadPool);
+    }
+
+    public ThreadPool getThreadPool()
+    {
+        return threadPool;
+    }
+
+    @ManagedAttribute("The utilization rate above which the thread pool is saturated")
+    public double getMaxUtilizationRate()
+    {
+        return maxUtilizationRate;
+    }
+
+    /**
+     * @param maxUtilizationRate the utilization rate, between 0 and 1, above which the thread pool is saturated
+     */
+    public void setMaxUtilizationRate(double maxUtilizationRate)
+    {
+        this.maxUtilizationRate = maxUtilizationRate;
+    }
+
+    @ManagedAttribute("The queue size above which the thread pool is saturated, or -1 for no limit")
+    public int getMaxQueueSize()
+    {
+        return maxQueueSize;
+    }
+
+    /**
+     * @param maxQueueSize the number of queued jobs above which the thread pool is saturated, or -1 for no limit
+     */
+    public void setMaxQueueSize(int maxQueueSize)
+    {
+        this.maxQueueSize = maxQueueSize;
+    }
+
+    @Override
+    public Result check()
+    {
+        String reason = null;
+        if (threadPool.isLowOnThreads())
+            reason = "low on threads";
+
+        Result result;
+        if (threadPool instanceof QueuedThreadPool)
+        {
+            QueuedThreadPool qtp = (QueuedThreadPool)threadPool;
+            double utilization = qtp.getUtilizationRate();
+            int queueSize = qtp.getQueueSize();
+            if (reason == null && utilization > maxUtilizationRate)
+                reason = "utilization rate " + utilization + " > " + maxUtilizationRate;
+            if (reason == null && maxQueueSize >= 0 && queueSize > maxQueueSize)
+                reason = "queue size " + queueSize + " > " + maxQueueSize;
+            result = reason == null ? Result.up() : Result.down(reason);
+            result = result.with("threads", qtp.getThreads())
+                .with("busyThreads", qtp.getBusyThreads())
+                .with("idleThreads", qtp.getIdleThreads())
+                .with("maxThreads", qtp.getMaxThreads())
+                .with("queueSize", queueSize)
+                .with("utilizationRate", utilization);
+        }
+        else
+        {
+            result = reason == null ? Result.up() : Result.down(reason);
+            result = result.with("threads", threadPool.getThreads())
+                .with("idleThreads", threadPool.getIdleThreads());
+        }
+        return result;
+    }
+}
@@ -0,0 +1,178 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.health;
+
+import java.util.EnumSet;
+import java.util.Map;
+import java.util.concurrent.atomic.AtomicBoolean;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class HealthCheckHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private HealthCheckHandler healthCheckHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        healthCheckHandler = new HealthCheckHandler();
+        healthCheckHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.ACCEPTED_202);
+            }
+        });
+        server.setHandler(healthCheckHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> parse(HttpTester.Response response)
+    {
+        return (Map<String, Object>)new JSON().fromJSON(response.getContent());
+    }
+
+    @Test
+    public void testProbesUp() throws Exception
+    {
+        healthCheckHandler.addHealthCheck(new ThreadPoolHealthCheck(server.getThreadPool()));
+        healthCheckHandler.addHealthCheck(new ConnectorHealthCheck(server));
+        server.start();
+
+        for (String path : new String[]{"/livez", "/readyz", "/startupz"})
+        {
+            HttpTester.Response response = get(path);
+            assertThat(path, response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.get(HttpHeader.CONTENT_TYPE), containsString("application/json"));
+            assertThat(response.get(HttpHeader.CACHE_CONTROL), is("no-store"));
+            assertThat(parse(response).get("status"), is("UP"));
+        }
+
+        @SuppressWarnings("unchecked")
+        Map<String, Object> checks = (Map<String, Object>)parse(get("/readyz")).get("checks");
+        assertThat(checks.get("threadPool"), notNullValue());
+        assertThat(checks.get("connectors"), notNullValue());
+        // The liveness probe does not run readiness checks.
+        assertThat(((Map<?, ?>)parse(get("/livez")).get("checks")).isEmpty(), is(true));
+
+        // Other requests are handled by the wrapped handler.
+        assertThat(get("/other").getStatus(), is(HttpStatus.ACCEPTED_202));
+    }
+
+    @Test
+    public void testCustomCheckDown() throws Exception
+    {
+        AtomicBoolean healthy = new AtomicBoolean(true);
+        healthCheckHandler.addHealthCheck(HealthCheck.from("custom", EnumSet.of(HealthCheck.Probe.LIVENESS), healthy::get));
+        server.start();
+
+        assertThat(get("/livez").getStatus(), is(HttpStatus.OK_200));
+
+        healthy.set(false);
+        HttpTester.Response response = get("/livez");
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        Map<String, Object> json = parse(response);
+        assertThat(json.get("status"), is("DOWN"));
+        assertThat(json.get("reason"), is("check custom down"));
+        @SuppressWarnings("unchecked")
+        Map<String, Object> check = (Map<String, Object>)((Map<String, Object>)json.get("checks")).get("custom");
+        assertThat(check.get("status"), is("DOWN"));
+
+        // Readiness is not affected.
+        assertThat(get("/readyz").getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testFailingCheckAddedToServer() throws Exception
+    {
+        server.addBean(new AbstractHealthCheck("failing", EnumSet.of(HealthCheck.Probe.READINESS))
+        {
+            @Override
+            public Result check()
+            {
+                throw new IllegalStateException("explicitly_thrown_by_test");
+            }
+        });
+        server.start();
+
+        HttpTester.Response response = get("/readyz");
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        @SuppressWarnings("unchecked")
+        Map<String, Object> check = (Map<String, Object>)((Map<String, Object>)parse(response).get("checks")).get("failing");
+        assertThat((String)check.get("reason"), containsString("explicitly_thrown_by_test"));
+    }
+
+    @Test
+    public void testGracefulShutdownFailsReadiness() throws Exception
+    {
+        server.start();
+        assertThat(get("/readyz").getStatus(), is(HttpStatus.OK_200));
+
+        healthCheckHandler.shutdown().get();
+
+        HttpTester.Response response = get("/readyz");
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(parse(response).get("reason"), is("shutting down"));
+        // Liveness and startup are not affected.
+        assertThat(get("/livez").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("/startupz").getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testHead() throws Exception
+    {
+        server.start();
+
+        String response = connector.getResponse("HEAD /readyz HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n");
+        assertThat(response, containsString("HTTP/1.1 200 OK"));
+        assertThat(response, not(containsString("\"status\"")));
+    }
+}
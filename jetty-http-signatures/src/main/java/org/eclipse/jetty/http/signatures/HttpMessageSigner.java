@@ -0,0 +1,232 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.SecureRandom;
+import java.security.SignatureException;
+import java.util.Base64;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpURI;
+
+/**
+ * <p>Signs HTTP requests as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421">RFC 9421</a>.</p>
+ * <p>A signature covers the configured components of the request, and it is
+ * carried by the {@code Signature-Input} and {@code Signature} headers under
+ * the configured {@link #setLabel(String) label}.</p>
+ * <p>The signature parameters always include {@code created} and
+ * {@code keyid}, and optionally {@code expires}, {@code nonce},
+ * {@code alg} and {@code tag}.</p>
+ * <p>Request content is signed by covering a {@code Content-Digest}
+ * header, which must be computed by the application.</p>
+ *
+ * @see HttpMessageVerifier
+ * @see HttpSignatureInterceptor
+ */
+public class HttpMessageSigner
+{
+    public static final String DEFAULT_LABEL = "sig1";
+
+    private final SecureRandom random = new SecureRandom();
+    private final SignatureKey key;
+    private final List<SignatureComponent> components;
+    private String label = DEFAULT_LABEL;
+    private long expiresIn = -1;
+    private boolean nonce;
+    private boolean includeAlgorithm;
+    private String tag;
+
+    /**
+     * @param key the key to sign with
+     * @param components the components covered by the signature
+     */
+    public HttpMessageSigner(SignatureKey key, SignatureComponent... components)
+    {
+        this(key, List.of(components));
+    }
+
+    /**
+     * @param key the key to sign with
+     * @param components the components covered by the signature
+     */
+    public HttpMessageSigner(SignatureKey key, List<SignatureComponent> components)
+    {
+        this.key = Objects.requireNonNull(key);
+        this.components = List.copyOf(components);
+    }
+
+    public SignatureKey getKey()
+    {
+        return key;
+    }
+
+    public List<SignatureComponent> getComponents()
+    {
+        return components;
+    }
+
+    /**
+     * @return the signature label
+     */
+    public String getLabel()
+    {
+        return label;
+    }
+
+    /**
+     * @param label the signature label, by default {@value #DEFAULT_LABEL}
+     */
+    public void setLabel(String label)
+    {
+        if (!StructuredFields.isKey(label))
+            throw new IllegalArgumentException("Invalid label: " + label);
+        this.label = label;
+    }
+
+    /**
+     * @return the time in seconds after which signatures expire, or -1 if signatures do not expire
+     */
+    public long getExpiresIn()
+    {
+        return expiresIn;
+    }
+
+    /**
+     * @param expiresIn the time in seconds after which signatures expire, or -1 if signatures do not expire
+     */
+    public void setExpiresIn(long expiresIn)
+    {
+        this.expiresIn = expiresIn;
+    }
+
+    /**
+     * @return whether signatures include a random {@code nonce} parameter
+     */
+    public boolean isNonce()
+    {
+        return nonce;
+    }
+
+    /**
+     * @param nonce whether signatures include a random {@code nonce} parameter
+     */
+    public void setNonce(boolean nonce)
+    {
+        this.nonce = nonce;
+    }
+
+    /**
+     * @return whether signatures include the {@code alg} parameter
+     */
+    public boolean isIncludeAlgorithm()
+    {
+        return includeAlgorithm;
+    }
+
+    /**
+     * @param includeAlgorithm whether signatures include the {@code alg} parameter
+     */
+    public void setIncludeAlgorithm(boolean includeAlgorithm)
+    {
+        this.includeAlgorithm = includeAlgorithm;
+    }
+
+    /**
+     * @return the {@code tag} parameter of signatures, or null
+     */
+    public String getTag()
+    {
+        return tag;
+    }
+
+    /**
+     * @param tag the {@code tag} parameter of signatures, identifying the application profile, or null
+     */
+    public void setTag(String tag)
+    {
+        this.tag = tag;
+    }
+
+    /**
+     * <p>Signs the given request.</p>
+     *
+     * @param method the request method
+     * @param uri the absolute request URI
+     * @param headers the request headers
+     * @return the {@code Signature-Input} and {@code Signature} fields to add to the request
+     * @throws SignatureException if the request cannot be signed, for example because a covered header is missing
+     */
+    public HttpFields sign(String method, HttpURI uri, HttpFields headers) throws SignatureException
+    {
+        SignatureInput input = newSignatureInput();
+        String base = SignatureBase.build(method, uri, headers, input);
+        byte[] signature;
+        try
+        {
+            signature = key.getAlgorithm().sign(key.getKey(), base.getBytes(StandardCharsets.US_ASCII));
+        }
+        catch (SignatureException x)
+        {
+            throw x;
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new SignatureException(x);
+        }
+        return HttpFields.build()
+            .add(SignatureInput.SIGNATURE_INPUT_HEADER, label + "=" + input.asString())
+            .add(SignatureInput.SIGNATURE_HEADER, label + "=:" + Base64.getEncoder().encodeToString(signature) + ":")
+            .asImmutable();
+    }
+
+    /**
+     * <p>Creates the input of a new signature.</p>
+     * <p>Subclasses may override to add other signature parameters.</p>
+     *
+     * @return the signature input
+     */
+    protected SignatureInput newSignatureInput()
+    {
+        long created = System.currentTimeMillis() / 1000;
+        Map<String, Object> parameters = new LinkedHashMap<>();
+        parameters.put(SignatureInput.CREATED, created);
+        if (expiresIn >= 0)
+            parameters.put(SignatureInput.EXPIRES, created + expiresIn);
+        if (nonce)
+        {
+            byte[] bytes = new byte[16];
+            random.nextBytes(bytes);
+            parameters.put(SignatureInput.NONCE, Base64.getUrlEncoder().withoutPadding().encodeToString(bytes));
+        }
+        if (includeAlgorithm)
+            parameters.put(SignatureInput.ALG, key.getAlgorithm().getName());
+        parameters.put(SignatureInput.KEY_ID, key.getKeyId());
+        if (tag != null)
+            parameters.put(SignatureInput.TAG, tag);
+        return new SignatureInput(components, parameters);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s,%s]", getClass().getSimpleName(), hashCode(), label, key, components);
+    }
+}
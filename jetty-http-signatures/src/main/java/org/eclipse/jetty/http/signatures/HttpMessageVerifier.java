@@ -0,0 +1,376 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.SignatureException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpURI;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Verifies the signatures of HTTP requests as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421">RFC 9421</a>.</p>
+ * <p>A signature is valid if:</p>
+ * <ul>
+ * <li>it covers all the {@link #setRequiredComponents(List) required components}</li>
+ * <li>its {@code keyid} parameter is resolved by the {@link SignatureKeyResolver}</li>
+ * <li>its {@code alg} parameter, if present, is the algorithm of the resolved key</li>
+ * <li>its {@code created} parameter is not in the future, and not older than the
+ * {@link #setMaxAge(long) max age}</li>
+ * <li>its {@code expires} parameter, if present, is not in the past</li>
+ * <li>the signature of the signature base verifies with the resolved key</li>
+ * </ul>
+ * <p>Times are compared allowing for the {@link #setClockSkew(long) clock skew}.</p>
+ * <p>If the request has multiple signatures, the one with the configured
+ * {@link #setLabel(String) label} is verified, or the first valid one
+ * if no label is configured.</p>
+ *
+ * @see HttpMessageSigner
+ * @see HttpSignatureHandler
+ */
+public class HttpMessageVerifier
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HttpMessageVerifier.class);
+
+    private final SignatureKeyResolver keyResolver;
+    private List<SignatureComponent> requiredComponents = List.of();
+    private String label;
+    private long maxAge = -1;
+    private long clockSkew = 30;
+    private boolean createdRequired = true;
+    private boolean expiresRequired;
+
+    /**
+     * @param keyResolver the resolver of the keys to verify signatures with
+     */
+    public HttpMessageVerifier(SignatureKeyResolver keyResolver)
+    {
+        this.keyResolver = Objects.requireNonNull(keyResolver);
+    }
+
+    public SignatureKeyResolver getKeyResolver()
+    {
+        return keyResolver;
+    }
+
+    /**
+     * @return the components that signatures must cover
+     */
+    public List<SignatureComponent> getRequiredComponents()
+    {
+        return requiredComponents;
+    }
+
+    /**
+     * @param requiredComponents the components that signatures must cover
+     */
+    public void setRequiredComponents(List<SignatureComponent> requiredComponents)
+    {
+        this.requiredComponents = List.copyOf(requiredComponents);
+    }
+
+    /**
+     * @return the label of the signature to verify, or null to verify any signature
+     */
+    public String getLabel()
+    {
+        return label;
+    }
+
+    /**
+     * @param label the label of the signature to verify, or null to verify any signature
+     */
+    public void setLabel(String label)
+    {
+        this.label = label;
+    }
+
+    /**
+     * @return the max age in seconds of signatures, or -1 for no max age
+     */
+    public long getMaxAge()
+    {
+        return maxAge;
+    }
+
+    /**
+     * @param maxAge the max age in seconds of signatures, computed from the {@code created} parameter, or -1 for no max age
+     */
+    public void setMaxAge(long maxAge)
+    {
+        this.maxAge = maxAge;
+    }
+
+    /**
+     * @return the clock skew in seconds allowed when comparing times
+     */
+    public long getClockSkew()
+    {
+        return clockSkew;
+    }
+
+    /**
+     * @param clockSkew the clock skew in seconds allowed when comparing times
+     */
+    public void setClockSkew(long clockSkew)
+    {
+        this.clockSkew = clockSkew;
+    }
+
+    /**
+     * @return whether signatures must have the {@code created} parameter
+     */
+    public boolean isCreatedRequired()
+    {
+        return createdRequired;
+    }
+
+    /**
+     * @param createdRequired whether signatures must have the {@code created} parameter
+     */
+    public void setCreatedRequired(boolean createdRequired)
+    {
+        this.createdRequired = createdRequired;
+    }
+
+    /**
+     * @return whether signatures must have the {@code expires} parameter
+     */
+    public boolean isExpiresRequired()
+    {
+        return expiresRequired;
+    }
+
+    /**
+     * @param expiresRequired whether signatures must have the {@code expires} parameter
+     */
+    public void setExpiresRequired(boolean expiresRequired)
+    {
+        this.expiresRequired = expiresRequired;
+    }
+
+    /**
+     * @param headers the request headers
+     * @return whether the request has signatures
+     */
+    public boolean isSigned(HttpFields headers)
+    {
+        return headers.contains(SignatureInput.SIGNATURE_INPUT_HEADER) || headers.contains(SignatureInput.SIGNATURE_HEADER);
+    }
+
+    /**
+     * <p>Verifies the signature of the given request.</p>
+     *
+     * @param method the request method
+     * @param uri the absolute request URI
+     * @param headers the request headers
+     * @return the verified signature
+     * @throws SignatureException if the request has no valid signature
+     */
+    public VerifiedSignature verify(String method, HttpURI uri, HttpFields headers) throws SignatureException
+    {
+        Map<String, SignatureInput> inputs;
+        Map<String, StructuredFields.Member> signatures;
+        try
+        {
+            inputs = SignatureInput.parse(join(headers.getValuesList(SignatureInput.SIGNATURE_INPUT_HEADER)));
+            signatures = StructuredFields.parseDictionary(join(headers.getValuesList(SignatureInput.SIGNATURE_HEADER)));
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new SignatureException("Invalid signature headers", x);
+        }
+
+        List<String> labels = label == null ? new ArrayList<>(inputs.keySet()) : List.of(label);
+        if (labels.isEmpty())
+            throw new SignatureException("Missing signature");
+
+        SignatureException failure = null;
+        for (String l : labels)
+        {
+            try
+            {
+                return verify(method, uri, headers, l, inputs.get(l), signatures.get(l));
+            }
+            catch (SignatureException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Invalid signature {} for {} {}", l, method, uri, x);
+                if (failure == null)
+                    failure = x;
+                else
+                    failure.addSuppressed(x);
+            }
+        }
+        throw failure;
+    }
+
+    private VerifiedSignature verify(String method, HttpURI uri, HttpFields headers, String label, SignatureInput input, StructuredFields.Member signature) throws SignatureException
+    {
+        if (input == null || signature == null)
+            throw new SignatureException("Missing signature " + label);
+        if (signature.getItem() == null || !(signature.getItem().getValue() instanceof byte[]))
+            throw new SignatureException("Invalid signature " + label);
+
+        for (SignatureComponent component : requiredComponents)
+        {
+            if (!input.getComponents().contains(component))
+                throw new SignatureException("Signature " + label + " does not cover " + component);
+        }
+
+        SignatureKey key;
+        try
+        {
+            checkTimes(label, input);
+
+            String keyId = input.getKeyId();
+            if (keyId == null)
+                throw new SignatureException("Signature " + label + " has no keyid");
+            key = keyResolver.resolve(keyId);
+            if (key == null)
+                throw new SignatureException("Signature " + label + " has unknown keyid " + keyId);
+            String algorithm = input.getAlgorithm();
+            if (algorithm != null && !algorithm.equals(key.getAlgorithm().getName()))
+                throw new SignatureException("Signature " + label + " has alg " + algorithm + " but key " + keyId + " is " + key.getAlgorithm());
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new SignatureException("Invalid signature parameters for " + label, x);
+        }
+
+        checkSignatureInput(label, input, key);
+
+        String base = SignatureBase.build(method, uri, headers, input);
+        boolean verified;
+        try
+        {
+            verified = key.getAlgorithm().verify(key.getKey(), base.getBytes(StandardCharsets.US_ASCII), (byte[])signature.getItem().getValue());
+        }
+        catch (SignatureException x)
+        {
+            throw x;
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new SignatureException(x);
+        }
+        if (!verified)
+            throw new SignatureException("Signature " + label + " does not verify");
+        return new VerifiedSignature(label, input, key);
+    }
+
+    private void checkTimes(String label, SignatureInput input) throws SignatureException
+    {
+        long now = System.currentTimeMillis() / 1000;
+        long created = input.getCreated();
+        if (created < 0)
+        {
+            if (createdRequired)
+                throw new SignatureException("Signature " + label + " has no created time");
+        }
+        else
+        {
+            if (created > now + clockSkew)
+                throw new SignatureException("Signature " + label + " created in the future");
+            if (maxAge >= 0 && now - created > maxAge + clockSkew)
+                throw new SignatureException("Signature " + label + " too old");
+        }
+
+        long expires = input.getExpires();
+        if (expires < 0)
+        {
+            if (expiresRequired)
+                throw new SignatureException("Signature " + label + " has no expires time");
+        }
+        else if (now > expires + clockSkew)
+        {
+            throw new SignatureException("Signature " + label + " expired");
+        }
+    }
+
+    /**
+     * <p>Performs additional checks on the input of a signature, before
+     * the signature is verified.</p>
+     * <p>Subclasses may override, for example to reject replayed
+     * {@code nonce} values, or signatures with an unexpected {@code tag}.</p>
+     *
+     * @param label the signature label
+     * @param input the signature input
+     * @param key the key resolved from the {@code keyid} parameter
+     * @throws SignatureException if the signature input is not acceptable
+     */
+    protected void checkSignatureInput(String label, SignatureInput input, SignatureKey key) throws SignatureException
+    {
+    }
+
+    private static String join(List<String> values)
+    {
+        return String.join(", ", values);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[label=%s,required=%s]", getClass().getSimpleName(), hashCode(), label, requiredComponents);
+    }
+
+    /**
+     * <p>A verified signature.</p>
+     */
+    public static class VerifiedSignature
+    {
+        private final String label;
+        private final SignatureInput input;
+        private final SignatureKey key;
+
+        private VerifiedSignature(String label, SignatureInput input, SignatureKey key)
+        {
+            this.label = label;
+            this.input = input;
+            this.key = key;
+        }
+
+        public String getLabel()
+        {
+            return label;
+        }
+
+        public SignatureInput getInput()
+        {
+            return input;
+        }
+
+        /**
+         * @return the key that verified the signature
+         */
+        public SignatureKey getKey()
+        {
+            return key;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s=%s]", getClass().getSimpleName(), hashCode(), label, input);
+        }
+    }
+}
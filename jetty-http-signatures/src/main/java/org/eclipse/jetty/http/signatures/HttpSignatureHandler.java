@@ -0,0 +1,127 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.io.IOException;
+import java.security.SignatureException;
+import java.util.Objects;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that verifies the HTTP message signatures of
+ * the requests with an {@link HttpMessageVerifier}, before passing them
+ * to the wrapped handler.</p>
+ * <p>Requests without a valid signature are rejected with status {@code 401}.
+ * If {@link #setSignatureRequired(boolean) signatures are not required},
+ * requests without signatures are passed to the wrapped handler, while
+ * requests with invalid signatures are still rejected.</p>
+ * <p>The {@link HttpMessageVerifier.VerifiedSignature verified signature} is available
+ * to applications as the {@value #VERIFIED_SIGNATURE_ATTRIBUTE} request attribute.</p>
+ */
+@ManagedObject("HTTP message signature verification handler")
+public class HttpSignatureHandler extends HandlerWrapper
+{
+    public static final String VERIFIED_SIGNATURE_ATTRIBUTE = "org.eclipse.jetty.http.signatures.verifiedSignature";
+    private static final Logger LOG = LoggerFactory.getLogger(HttpSignatureHandler.class);
+
+    private final LongAdder verified = new LongAdder();
+    private final LongAdder rejected = new LongAdder();
+    private final HttpMessageVerifier verifier;
+    private boolean signatureRequired = true;
+
+    public HttpSignatureHandler(HttpMessageVerifier verifier)
+    {
+        this.verifier = Objects.requireNonNull(verifier);
+    }
+
+    public HttpMessageVerifier getVerifier()
+    {
+        return verifier;
+    }
+
+    @ManagedAttribute("Whether requests must be signed")
+    public boolean isSignatureRequired()
+    {
+        return signatureRequired;
+    }
+
+    public void setSignatureRequired(boolean signatureRequired)
+    {
+        this.signatureRequired = signatureRequired;
+    }
+
+    @ManagedAttribute("The number of requests with verified signatures")
+    public long getVerifiedRequests()
+    {
+        return verified.sum();
+    }
+
+    @ManagedAttribute("The number of requests rejected because of missing or invalid signatures")
+    public long getRejectedRequests()
+    {
+        return rejected.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        verified.reset();
+        rejected.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpFields headers = baseRequest.getHttpFields();
+        if (!signatureRequired && !verifier.isSigned(headers))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        HttpMessageVerifier.VerifiedSignature signature;
+        try
+        {
+            signature = verifier.verify(baseRequest.getMethod(), baseRequest.getHttpURI(), headers);
+        }
+        catch (SignatureException x)
+        {
+            rejected.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejected {}", baseRequest, x);
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.UNAUTHORIZED_401);
+            return;
+        }
+
+        verified.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Verified {} for {}", signature, baseRequest);
+        request.setAttribute(VERIFIED_SIGNATURE_ATTRIBUTE, signature);
+        super.handle(target, baseRequest, request, response);
+    }
+}
@@ -0,0 +1,106 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.util.ListIterator;
+import java.util.Objects;
+
+import org.eclipse.jetty.client.Interceptor;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpURI;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link Interceptor} that signs the requests sent by
+ * {@code HttpClient} with an {@link HttpMessageSigner}.</p>
+ * <pre>{@code
+ * SignatureKey key = new SignatureKey("client-key", SignatureAlgorithm.ECDSA_P256_SHA256, privateKey);
+ * HttpMessageSigner signer = new HttpMessageSigner(key,
+ *     SignatureComponent.METHOD, SignatureComponent.TARGET_URI, SignatureComponent.of("content-type"));
+ * httpClient.addInterceptor(new HttpSignatureInterceptor(signer));
+ * }</pre>
+ * <p>Requests are signed just before they are sent, so that the signature
+ * may cover the headers that are added by {@code HttpClient}, such as
+ * {@code Content-Type} and {@code Content-Length}.
+ * Requests that cannot be signed, for example because they do not have
+ * a covered header, are aborted.</p>
+ */
+public class HttpSignatureInterceptor implements Interceptor
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HttpSignatureInterceptor.class);
+
+    private final HttpMessageSigner signer;
+
+    public HttpSignatureInterceptor(HttpMessageSigner signer)
+    {
+        this.signer = Objects.requireNonNull(signer);
+    }
+
+    public HttpMessageSigner getSigner()
+    {
+        return signer;
+    }
+
+    @Override
+    public void intercept(Request request, Response.Listener listener, Chain chain)
+    {
+        request.onRequestBegin(this::sign);
+        chain.proceed(request, listener);
+    }
+
+    private void sign(Request request)
+    {
+        try
+        {
+            HttpURI uri = HttpURI.build()
+                .scheme(request.getScheme())
+                .host(request.getHost())
+                .port(request.getPort())
+                .path(request.getPath())
+                .query(request.getQuery());
+            HttpFields fields = signer.sign(request.getMethod(), uri, request.getHeaders());
+            request.headers(headers ->
+            {
+                // Replace the signature of a previous attempt, for example when retrying.
+                String prefix = signer.getLabel() + "=";
+                for (ListIterator<HttpField> i = headers.listIterator(); i.hasNext();)
+                {
+                    HttpField field = i.next();
+                    if ((field.is(SignatureInput.SIGNATURE_INPUT_HEADER) || field.is(SignatureInput.SIGNATURE_HEADER)) &&
+                        field.getValue() != null && field.getValue().startsWith(prefix))
+                        i.remove();
+                }
+                headers.add(fields);
+            });
+            if (LOG.isDebugEnabled())
+                LOG.debug("Signed {} with {}", request, fields);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not sign {}", request, x);
+            request.abort(x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), signer);
+    }
+}
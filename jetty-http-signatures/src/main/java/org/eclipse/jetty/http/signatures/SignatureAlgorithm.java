@@ -0,0 +1,152 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.security.GeneralSecurityException;
+import java.security.InvalidKeyException;
+import java.security.Key;
+import java.security.MessageDigest;
+import java.security.PrivateKey;
+import java.security.PublicKey;
+import java.security.Signature;
+import java.security.spec.MGF1ParameterSpec;
+import java.security.spec.PSSParameterSpec;
+import javax.crypto.Mac;
+
+/**
+ * <p>The HTTP message signature algorithms defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421#section-3.3">RFC 9421</a>.</p>
+ * <p>Asymmetric algorithms sign with a {@link PrivateKey} and verify with a
+ * {@link PublicKey}, while {@link #HMAC_SHA256} signs and verifies with the
+ * same secret key.</p>
+ * <p>{@link #ED25519} requires a JVM that supports the {@code Ed25519}
+ * signature algorithm, such as Java 15 or later.</p>
+ */
+public enum SignatureAlgorithm
+{
+    RSA_PSS_SHA512("rsa-pss-sha512"),
+    RSA_V1_5_SHA256("rsa-v1_5-sha256"),
+    HMAC_SHA256("hmac-sha256"),
+    ECDSA_P256_SHA256("ecdsa-p256-sha256"),
+    ECDSA_P384_SHA384("ecdsa-p384-sha384"),
+    ED25519("ed25519");
+
+    private final String name;
+
+    SignatureAlgorithm(String name)
+    {
+        this.name = name;
+    }
+
+    /**
+     * @return the algorithm name, as used in the {@code alg} signature parameter
+     */
+    public String getName()
+    {
+        return name;
+    }
+
+    /**
+     * @param name the algorithm name, as used in the {@code alg} signature parameter
+     * @return the algorithm with the given name, or null if there is no such algorithm
+     */
+    public static SignatureAlgorithm from(String name)
+    {
+        for (SignatureAlgorithm algorithm : values())
+        {
+            if (algorithm.name.equals(name))
+                return algorithm;
+        }
+        return null;
+    }
+
+    /**
+     * <p>Signs the given signature base.</p>
+     *
+     * @param key the private key, or the secret key for {@link #HMAC_SHA256}
+     * @param base the signature base bytes
+     * @return the signature bytes
+     * @throws GeneralSecurityException if the signature cannot be computed
+     */
+    public byte[] sign(Key key, byte[] base) throws GeneralSecurityException
+    {
+        if (this == HMAC_SHA256)
+            return hmac(key, base);
+        if (!(key instanceof PrivateKey))
+            throw new InvalidKeyException("Not a private key for " + name);
+        Signature signature = newSignature();
+        signature.initSign((PrivateKey)key);
+        signature.update(base);
+        return signature.sign();
+    }
+
+    /**
+     * <p>Verifies the given signature of the given signature base.</p>
+     *
+     * @param key the public key, or the secret key for {@link #HMAC_SHA256}
+     * @param base the signature base bytes
+     * @param signature the signature bytes
+     * @return whether the signature is valid
+     * @throws GeneralSecurityException if the signature cannot be verified
+     */
+    public boolean verify(Key key, byte[] base, byte[] signature) throws GeneralSecurityException
+    {
+        // Use a constant time comparison to not leak information about the expected value.
+        if (this == HMAC_SHA256)
+            return MessageDigest.isEqual(hmac(key, base), signature);
+        if (!(key instanceof PublicKey))
+            throw new InvalidKeyException("Not a public key for " + name);
+        Signature verifier = newSignature();
+        verifier.initVerify((PublicKey)key);
+        verifier.update(base);
+        return verifier.verify(signature);
+    }
+
+    private byte[] hmac(Key key, byte[] base) throws GeneralSecurityException
+    {
+        Mac mac = Mac.getInstance("HmacSHA256");
+        mac.init(key);
+        return mac.doFinal(base);
+    }
+
+    private Signature newSignature() throws GeneralSecurityException
+    {
+        switch (this)
+        {
+            case RSA_PSS_SHA512:
+            {
+                Signature signature = Signature.getInstance("RSASSA-PSS");
+                signature.setParameter(new PSSParameterSpec("SHA-512", "MGF1", MGF1ParameterSpec.SHA512, 64, 1));
+                return signature;
+            }
+            case RSA_V1_5_SHA256:
+                return Signature.getInstance("SHA256withRSA");
+            // RFC 9421 ECDSA signatures are the concatenation of r and s, not DER encoded.
+            case ECDSA_P256_SHA256:
+                return Signature.getInstance("SHA256withECDSAinP1363Format");
+            case ECDSA_P384_SHA384:
+                return Signature.getInstance("SHA384withECDSAinP1363Format");
+            case ED25519:
+                return Signature.getInstance("Ed25519");
+            default:
+                throw new IllegalStateException();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return name;
+    }
+}
@@ -0,0 +1,188 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.nio.charset.StandardCharsets;
+import java.security.SignatureException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.TypeUtil;
+import org.eclipse.jetty.util.UrlEncoded;
+
+/**
+ * <p>Creates the signature base of an HTTP request, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421#section-2.5">RFC 9421</a>.</p>
+ * <p>The signature base has one line for each covered component, followed by
+ * the {@code @signature-params} line, for example:</p>
+ * <pre>
+ * "@method": POST
+ * "@authority": example.com
+ * "content-type": application/json
+ * "@signature-params": ("@method" "@authority" "content-type");created=1618884473;keyid="test-key"
+ * </pre>
+ */
+public class SignatureBase
+{
+    private SignatureBase()
+    {
+    }
+
+    /**
+     * <p>Creates the signature base of the given request.</p>
+     *
+     * @param method the request method
+     * @param uri the absolute request URI
+     * @param headers the request headers
+     * @param input the signature input
+     * @return the signature base
+     * @throws SignatureException if a covered component is missing or not supported
+     */
+    public static String build(String method, HttpURI uri, HttpFields headers, SignatureInput input) throws SignatureException
+    {
+        StringBuilder builder = new StringBuilder();
+        for (SignatureComponent component : input.getComponents())
+        {
+            for (String value : values(method, uri, headers, component))
+            {
+                for (int i = 0; i < value.length(); ++i)
+                {
+                    char c = value.charAt(i);
+                    if (c < 0x20 && c != '\t' || c >= 0x7F)
+                        throw new SignatureException("Invalid value for component " + component);
+                }
+                builder.append(component.asString()).append(": ").append(value).append('\n');
+            }
+        }
+        builder.append('"').append(SignatureComponent.SIGNATURE_PARAMS).append("\": ").append(input.asString());
+        return builder.toString();
+    }
+
+    private static List<String> values(String method, HttpURI uri, HttpFields headers, SignatureComponent component) throws SignatureException
+    {
+        String name = component.getName();
+        Map<String, Object> parameters = component.getParameters();
+        if (SignatureComponent.QUERY_PARAM.equals(name))
+        {
+            Object paramName = parameters.get("name");
+            if (parameters.size() != 1 || !(paramName instanceof String))
+                throw new SignatureException("Unsupported component " + component);
+            List<String> values = queryParams(uri.getQuery(), (String)paramName);
+            if (values.isEmpty())
+                throw new SignatureException("Missing component " + component);
+            return values;
+        }
+
+        if (!parameters.isEmpty())
+            throw new SignatureException("Unsupported component " + component);
+
+        if (component.isDerived())
+        {
+            String path = StringUtil.isEmpty(uri.getPath()) ? "/" : uri.getPath();
+            String query = uri.getQuery();
+            switch (name)
+            {
+                case "@method":
+                    return List.of(method);
+                case "@target-uri":
+                    return List.of(StringUtil.asciiToLowerCase(uri.getScheme()) + "://" + authority(uri) + requestTarget(path, query));
+                case "@authority":
+                    return List.of(authority(uri));
+                case "@scheme":
+                    return List.of(StringUtil.asciiToLowerCase(uri.getScheme()));
+                case "@request-target":
+                    return List.of(requestTarget(path, query));
+                case "@path":
+                    return List.of(path);
+                case "@query":
+                    return List.of(query == null ? "?" : "?" + query);
+                default:
+                    throw new SignatureException("Unsupported component " + component);
+            }
+        }
+
+        List<String> values = headers.getValuesList(name);
+        if (values.isEmpty())
+            throw new SignatureException("Missing component " + component);
+        // Multiple fields are combined, with their values trimmed.
+        StringBuilder builder = new StringBuilder();
+        for (String value : values)
+        {
+            if (builder.length() > 0)
+                builder.append(", ");
+            builder.append(value == null ? "" : value.trim());
+        }
+        return List.of(builder.toString());
+    }
+
+    private static String authority(HttpURI uri)
+    {
+        String host = StringUtil.asciiToLowerCase(uri.getHost());
+        int port = HttpScheme.normalizePort(uri.getScheme(), uri.getPort());
+        return port > 0 ? host + ":" + port : host;
+    }
+
+    private static String requestTarget(String path, String query)
+    {
+        return query == null ? path : path + "?" + query;
+    }
+
+    private static List<String> queryParams(String query, String name) throws SignatureException
+    {
+        List<String> values = new ArrayList<>();
+        if (query == null)
+            return values;
+        try
+        {
+            String decodedName = UrlEncoded.decodeString(name);
+            for (String pair : query.split("&"))
+            {
+                int equals = pair.indexOf('=');
+                String key = UrlEncoded.decodeString(equals < 0 ? pair : pair.substring(0, equals));
+                if (key.equals(decodedName))
+                    values.add(encode(equals < 0 ? "" : UrlEncoded.decodeString(pair.substring(equals + 1))));
+            }
+            return values;
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new SignatureException("Invalid query parameter " + name, x);
+        }
+    }
+
+    private static String encode(String value)
+    {
+        // Re-encode like the WHATWG application/x-www-form-urlencoded
+        // serializer, but with spaces encoded as %20, as per RFC 9421.
+        StringBuilder builder = new StringBuilder();
+        for (byte b : value.getBytes(StandardCharsets.UTF_8))
+        {
+            if (b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '*' || b == '-' || b == '.' || b == '_')
+            {
+                builder.append((char)b);
+            }
+            else
+            {
+                builder.append('%');
+                TypeUtil.toHex(b, builder);
+            }
+        }
+        return builder.toString();
+    }
+}
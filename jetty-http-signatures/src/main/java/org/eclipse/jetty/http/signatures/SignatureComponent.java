@@ -0,0 +1,162 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.StringUtil;
+
+/**
+ * <p>A component covered by an HTTP message signature, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421#section-2">RFC 9421</a>.</p>
+ * <p>A component is either a derived component, whose name starts with
+ * {@code @} such as {@link #METHOD}, or a header field, whose name is
+ * the lower-case field name such as {@code content-type}.</p>
+ * <p>Component parameters are supported only for the {@code name}
+ * parameter of {@link #queryParam(String) query parameters}.</p>
+ */
+public class SignatureComponent
+{
+    public static final SignatureComponent METHOD = new SignatureComponent("@method");
+    public static final SignatureComponent TARGET_URI = new SignatureComponent("@target-uri");
+    public static final SignatureComponent AUTHORITY = new SignatureComponent("@authority");
+    public static final SignatureComponent SCHEME = new SignatureComponent("@scheme");
+    public static final SignatureComponent REQUEST_TARGET = new SignatureComponent("@request-target");
+    public static final SignatureComponent PATH = new SignatureComponent("@path");
+    public static final SignatureComponent QUERY = new SignatureComponent("@query");
+    static final String QUERY_PARAM = "@query-param";
+    static final String SIGNATURE_PARAMS = "@signature-params";
+
+    private final String name;
+    private final Map<String, Object> parameters;
+
+    private SignatureComponent(String name)
+    {
+        this(name, Map.of());
+    }
+
+    private SignatureComponent(String name, Map<String, Object> parameters)
+    {
+        this.name = name;
+        this.parameters = parameters;
+    }
+
+    /**
+     * @param name the derived component name, such as {@code @method},
+     * or the header field name, such as {@code Content-Type}
+     * @return the component with the given name
+     */
+    public static SignatureComponent of(String name)
+    {
+        if (StringUtil.isBlank(name))
+            throw new IllegalArgumentException("Invalid component name: " + name);
+        if (QUERY_PARAM.equals(name) || SIGNATURE_PARAMS.equals(name))
+            throw new IllegalArgumentException("Invalid component name: " + name);
+        return new SignatureComponent(StringUtil.asciiToLowerCase(name));
+    }
+
+    /**
+     * @param name the query parameter name
+     * @return the component for the query parameter with the given name
+     */
+    public static SignatureComponent queryParam(String name)
+    {
+        Map<String, Object> parameters = new LinkedHashMap<>();
+        parameters.put("name", Objects.requireNonNull(name));
+        return new SignatureComponent(QUERY_PARAM, Collections.unmodifiableMap(parameters));
+    }
+
+    /**
+     * @param item the serialized component identifier, such as {@code "@method"}
+     * @return the component with the given identifier
+     * @throws IllegalArgumentException if the identifier is not valid
+     */
+    public static SignatureComponent parse(String item)
+    {
+        return from(StructuredFields.parseItem(item));
+    }
+
+    static SignatureComponent from(StructuredFields.Item item)
+    {
+        if (!(item.getValue() instanceof String))
+            throw new IllegalArgumentException("Component identifier is not a string");
+        String name = (String)item.getValue();
+        if (!name.equals(StringUtil.asciiToLowerCase(name)))
+            throw new IllegalArgumentException("Component name is not lower-case: " + name);
+        if (item.getParameters().isEmpty())
+            return of(name);
+        return new SignatureComponent(name, Collections.unmodifiableMap(new LinkedHashMap<>(item.getParameters())));
+    }
+
+    /**
+     * @return the component name
+     */
+    public String getName()
+    {
+        return name;
+    }
+
+    /**
+     * @return the component parameters
+     */
+    public Map<String, Object> getParameters()
+    {
+        return parameters;
+    }
+
+    /**
+     * @return whether this is a derived component rather than a header field
+     */
+    public boolean isDerived()
+    {
+        return name.startsWith("@");
+    }
+
+    /**
+     * @return the serialized component identifier, as used in the signature base
+     */
+    public String asString()
+    {
+        StringBuilder builder = new StringBuilder();
+        StructuredFields.serializeBareItem(builder, name);
+        StructuredFields.serializeParameters(builder, parameters);
+        return builder.toString();
+    }
+
+    @Override
+    public boolean equals(Object obj)
+    {
+        if (this == obj)
+            return true;
+        if (!(obj instanceof SignatureComponent))
+            return false;
+        SignatureComponent that = (SignatureComponent)obj;
+        return name.equals(that.name) && parameters.equals(that.parameters);
+    }
+
+    @Override
+    public int hashCode()
+    {
+        return Objects.hash(name, parameters);
+    }
+
+    @Override
+    public String toString()
+    {
+        return asString();
+    }
+}
@@ -0,0 +1,188 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+/**
+ * <p>The input of an HTTP message signature: the ordered list of covered
+ * {@link SignatureComponent components} and the signature parameters,
+ * as carried by the {@code Signature-Input} header, see
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9421#section-2.3">RFC 9421</a>.</p>
+ * <p>The {@code created} and {@code expires} parameters are integer times
+ * in seconds since the epoch; the {@code nonce}, {@code alg}, {@code keyid}
+ * and {@code tag} parameters are strings.</p>
+ */
+public class SignatureInput
+{
+    public static final String SIGNATURE_INPUT_HEADER = "Signature-Input";
+    public static final String SIGNATURE_HEADER = "Signature";
+    public static final String CREATED = "created";
+    public static final String EXPIRES = "expires";
+    public static final String NONCE = "nonce";
+    public static final String ALG = "alg";
+    public static final String KEY_ID = "keyid";
+    public static final String TAG = "tag";
+
+    private final List<SignatureComponent> components;
+    private final Map<String, Object> parameters;
+
+    /**
+     * @param components the covered components, in order
+     * @param parameters the signature parameters, in order
+     */
+    public SignatureInput(List<SignatureComponent> components, Map<String, Object> parameters)
+    {
+        if (components.size() != components.stream().distinct().count())
+            throw new IllegalArgumentException("Duplicate components: " + components);
+        this.components = List.copyOf(components);
+        this.parameters = Collections.unmodifiableMap(new LinkedHashMap<>(parameters));
+    }
+
+    /**
+     * <p>Parses the value of a {@code Signature-Input} header.</p>
+     *
+     * @param value the header value
+     * @return the signature inputs, keyed by signature label
+     * @throws IllegalArgumentException if the value cannot be parsed
+     */
+    public static Map<String, SignatureInput> parse(String value)
+    {
+        Map<String, SignatureInput> result = new LinkedHashMap<>();
+        for (Map.Entry<String, StructuredFields.Member> entry : StructuredFields.parseDictionary(value).entrySet())
+        {
+            List<StructuredFields.Item> list = entry.getValue().getInnerList();
+            if (list == null)
+                throw new IllegalArgumentException("Signature input is not an inner list: " + entry.getKey());
+            List<SignatureComponent> components = new ArrayList<>(list.size());
+            for (StructuredFields.Item item : list)
+            {
+                components.add(SignatureComponent.from(item));
+            }
+            result.put(entry.getKey(), new SignatureInput(components, entry.getValue().getParameters()));
+        }
+        return result;
+    }
+
+    /**
+     * @return the covered components, in order
+     */
+    public List<SignatureComponent> getComponents()
+    {
+        return components;
+    }
+
+    /**
+     * @return the signature parameters, in order
+     */
+    public Map<String, Object> getParameters()
+    {
+        return parameters;
+    }
+
+    /**
+     * @return the {@code created} parameter, or -1 if absent
+     */
+    public long getCreated()
+    {
+        return getLong(CREATED);
+    }
+
+    /**
+     * @return the {@code expires} parameter, or -1 if absent
+     */
+    public long getExpires()
+    {
+        return getLong(EXPIRES);
+    }
+
+    /**
+     * @return the {@code nonce} parameter, or null if absent
+     */
+    public String getNonce()
+    {
+        return getString(NONCE);
+    }
+
+    /**
+     * @return the {@code alg} parameter, or null if absent
+     */
+    public String getAlgorithm()
+    {
+        return getString(ALG);
+    }
+
+    /**
+     * @return the {@code keyid} parameter, or null if absent
+     */
+    public String getKeyId()
+    {
+        return getString(KEY_ID);
+    }
+
+    /**
+     * @return the {@code tag} parameter, or null if absent
+     */
+    public String getTag()
+    {
+        return getString(TAG);
+    }
+
+    private long getLong(String name)
+    {
+        Object value = parameters.get(name);
+        if (value == null)
+            return -1;
+        if (value instanceof Long)
+            return (Long)value;
+        throw new IllegalArgumentException("Parameter " + name + " is not an integer");
+    }
+
+    private String getString(String name)
+    {
+        Object value = parameters.get(name);
+        if (value == null || value instanceof String)
+            return (String)value;
+        throw new IllegalArgumentException("Parameter " + name + " is not a string");
+    }
+
+    /**
+     * @return the serialized signature input, as used in the {@code Signature-Input}
+     * header and in the {@code @signature-params} line of the signature base
+     */
+    public String asString()
+    {
+        StringBuilder builder = new StringBuilder();
+        builder.append('(');
+        for (int i = 0; i < components.size(); ++i)
+        {
+            if (i > 0)
+                builder.append(' ');
+            builder.append(components.get(i).asString());
+        }
+        builder.append(')');
+        StructuredFields.serializeParameters(builder, parameters);
+        return builder.toString();
+    }
+
+    @Override
+    public String toString()
+    {
+        return asString();
+    }
+}
@@ -0,0 +1,65 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.security.Key;
+import java.util.Objects;
+
+/**
+ * <p>A key used to sign or verify HTTP message signatures, identified by
+ * the {@code keyid} signature parameter and bound to a
+ * {@link SignatureAlgorithm}.</p>
+ * <p>Binding the algorithm to the key, rather than trusting the {@code alg}
+ * signature parameter, prevents an attacker from choosing the algorithm
+ * used to verify a signature.</p>
+ */
+public class SignatureKey
+{
+    private final String keyId;
+    private final SignatureAlgorithm algorithm;
+    private final Key key;
+
+    /**
+     * @param keyId the key identifier
+     * @param algorithm the algorithm used with the key
+     * @param key the private, public or secret key
+     */
+    public SignatureKey(String keyId, SignatureAlgorithm algorithm, Key key)
+    {
+        this.keyId = Objects.requireNonNull(keyId);
+        this.algorithm = Objects.requireNonNull(algorithm);
+        this.key = Objects.requireNonNull(key);
+    }
+
+    public String getKeyId()
+    {
+        return keyId;
+    }
+
+    public SignatureAlgorithm getAlgorithm()
+    {
+        return algorithm;
+    }
+
+    public Key getKey()
+    {
+        return key;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), keyId, algorithm);
+    }
+}
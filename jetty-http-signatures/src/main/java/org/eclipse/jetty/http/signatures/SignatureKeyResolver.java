@@ -0,0 +1,50 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.util.HashMap;
+import java.util.Map;
+
+/**
+ * <p>Resolves the keys used to verify HTTP message signatures
+ * from the {@code keyid} signature parameter.</p>
+ * <p>Implementations may look up keys in a key store, a database or a
+ * remote service; a resolver for a fixed set of keys is returned by
+ * {@link #of(SignatureKey...)}.</p>
+ */
+@FunctionalInterface
+public interface SignatureKeyResolver
+{
+    /**
+     * <p>Resolves the key with the given identifier.</p>
+     *
+     * @param keyId the key identifier
+     * @return the key, or null if the key is unknown or not trusted
+     */
+    public SignatureKey resolve(String keyId);
+
+    /**
+     * @param keys the keys to resolve
+     * @return a resolver for the given keys
+     */
+    public static SignatureKeyResolver of(SignatureKey... keys)
+    {
+        Map<String, SignatureKey> map = new HashMap<>();
+        for (SignatureKey key : keys)
+        {
+            map.put(key.getKeyId(), key);
+        }
+        return map::get;
+    }
+}
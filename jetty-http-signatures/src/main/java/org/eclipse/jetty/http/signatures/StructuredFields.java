@@ -0,0 +1,463 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+/**
+ * <p>A minimal parser and serializer of the
+ * <a href="https://datatracker.ietf.org/doc/html/rfc8941">RFC 8941</a>
+ * structured field values used by HTTP message signatures:
+ * dictionaries whose members are items or inner lists.</p>
+ * <p>Decimal values are not supported.</p>
+ */
+class StructuredFields
+{
+    private StructuredFields()
+    {
+    }
+
+    /**
+     * @param value the dictionary field value
+     * @return the dictionary members, in order
+     * @throws IllegalArgumentException if the value is not a valid dictionary
+     */
+    static Map<String, Member> parseDictionary(String value)
+    {
+        Parser parser = new Parser(value);
+        Map<String, Member> dictionary = new LinkedHashMap<>();
+        parser.skipSpaces();
+        while (!parser.isEnd())
+        {
+            String key = parser.parseKey();
+            Member member;
+            if (parser.consume('='))
+            {
+                if (parser.peek() == '(')
+                {
+                    List<Item> list = parser.parseInnerList();
+                    member = new Member(null, list, parser.parseParameters());
+                }
+                else
+                {
+                    Object bareItem = parser.parseBareItem();
+                    member = new Member(new Item(bareItem, parser.parseParameters()), null, null);
+                }
+            }
+            else
+            {
+                member = new Member(new Item(Boolean.TRUE, parser.parseParameters()), null, null);
+            }
+            dictionary.put(key, member);
+
+            parser.skipWhiteSpace();
+            if (parser.isEnd())
+                break;
+            if (!parser.consume(','))
+                throw parser.error("Expected ','");
+            parser.skipWhiteSpace();
+            if (parser.isEnd())
+                throw parser.error("Trailing ','");
+        }
+        return dictionary;
+    }
+
+    /**
+     * @param value the item field value
+     * @return the item
+     * @throws IllegalArgumentException if the value is not a valid item
+     */
+    static Item parseItem(String value)
+    {
+        Parser parser = new Parser(value);
+        parser.skipSpaces();
+        Item item = new Item(parser.parseBareItem(), parser.parseParameters());
+        parser.skipSpaces();
+        if (!parser.isEnd())
+            throw parser.error("Unexpected characters");
+        return item;
+    }
+
+    /**
+     * @param key the string to test
+     * @return whether the given string is a valid dictionary or parameter key
+     */
+    static boolean isKey(String key)
+    {
+        if (key == null || key.isEmpty())
+            return false;
+        for (int i = 0; i < key.length(); ++i)
+        {
+            if (!isKeyChar(key.charAt(i), i == 0))
+                return false;
+        }
+        return true;
+    }
+
+    private static boolean isKeyChar(char c, boolean first)
+    {
+        if (c >= 'a' && c <= 'z' || c == '*')
+            return true;
+        return !first && (c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.');
+    }
+
+    static void serializeBareItem(StringBuilder builder, Object value)
+    {
+        if (value instanceof Long || value instanceof Integer)
+        {
+            builder.append(value);
+        }
+        else if (value instanceof String)
+        {
+            String string = (String)value;
+            builder.append('"');
+            for (int i = 0; i < string.length(); ++i)
+            {
+                char c = string.charAt(i);
+                if (c < 0x20 || c >= 0x7F)
+                    throw new IllegalArgumentException("Invalid string character: " + string);
+                if (c == '"' || c == '\\')
+                    builder.append('\\');
+                builder.append(c);
+            }
+            builder.append('"');
+        }
+        else if (value instanceof Token)
+        {
+            builder.append(((Token)value).getValue());
+        }
+        else if (value instanceof byte[])
+        {
+            builder.append(':').append(Base64.getEncoder().encodeToString((byte[])value)).append(':');
+        }
+        else if (value instanceof Boolean)
+        {
+            builder.append((Boolean)value ? "?1" : "?0");
+        }
+        else
+        {
+            throw new IllegalArgumentException("Unsupported value: " + value);
+        }
+    }
+
+    static void serializeParameters(StringBuilder builder, Map<String, Object> parameters)
+    {
+        for (Map.Entry<String, Object> entry : parameters.entrySet())
+        {
+            builder.append(';').append(entry.getKey());
+            if (!Boolean.TRUE.equals(entry.getValue()))
+            {
+                builder.append('=');
+                serializeBareItem(builder, entry.getValue());
+            }
+        }
+    }
+
+    /**
+     * <p>A dictionary member, either an item or an inner list with parameters.</p>
+     */
+    static class Member
+    {
+        private final Item item;
+        private final List<Item> innerList;
+        private final Map<String, Object> parameters;
+
+        private Member(Item item, List<Item> innerList, Map<String, Object> parameters)
+        {
+            this.item = item;
+            this.innerList = innerList;
+            this.parameters = parameters;
+        }
+
+        Item getItem()
+        {
+            return item;
+        }
+
+        List<Item> getInnerList()
+        {
+            return innerList;
+        }
+
+        Map<String, Object> getParameters()
+        {
+            return item != null ? item.getParameters() : parameters;
+        }
+    }
+
+    /**
+     * <p>An item: a bare value with parameters.</p>
+     */
+    static class Item
+    {
+        private final Object value;
+        private final Map<String, Object> parameters;
+
+        Item(Object value, Map<String, Object> parameters)
+        {
+            this.value = value;
+            this.parameters = parameters;
+        }
+
+        Object getValue()
+        {
+            return value;
+        }
+
+        Map<String, Object> getParameters()
+        {
+            return parameters;
+        }
+    }
+
+    /**
+     * <p>A token value, to tell it apart from a string value.</p>
+     */
+    static class Token
+    {
+        private final String value;
+
+        Token(String value)
+        {
+            this.value = value;
+        }
+
+        String getValue()
+        {
+            return value;
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            return obj instanceof Token && value.equals(((Token)obj).value);
+        }
+
+        @Override
+        public int hashCode()
+        {
+            return value.hashCode();
+        }
+
+        @Override
+        public String toString()
+        {
+            return value;
+        }
+    }
+
+    private static class Parser
+    {
+        private final String value;
+        private int index;
+
+        private Parser(String value)
+        {
+            this.value = value;
+        }
+
+        private boolean isEnd()
+        {
+            return index >= value.length();
+        }
+
+        private char peek()
+        {
+            return isEnd() ? 0 : value.charAt(index);
+        }
+
+        private boolean consume(char c)
+        {
+            if (peek() != c)
+                return false;
+            ++index;
+            return true;
+        }
+
+        private void skipSpaces()
+        {
+            while (peek() == ' ')
+            {
+                ++index;
+            }
+        }
+
+        private void skipWhiteSpace()
+        {
+            while (peek() == ' ' || peek() == '\t')
+            {
+                ++index;
+            }
+        }
+
+        private IllegalArgumentException error(String message)
+        {
+            return new IllegalArgumentException(message + " at index " + index + " in: " + value);
+        }
+
+        private String parseKey()
+        {
+            if (!isKeyChar(peek(), true))
+                throw error("Invalid key");
+            int start = index;
+            while (!isEnd() && isKeyChar(peek(), false))
+            {
+                ++index;
+            }
+            return value.substring(start, index);
+        }
+
+        private List<Item> parseInnerList()
+        {
+            if (!consume('('))
+                throw error("Expected '('");
+            List<Item> list = new ArrayList<>();
+            while (true)
+            {
+                skipSpaces();
+                if (consume(')'))
+                    return list;
+                Object bareItem = parseBareItem();
+                list.add(new Item(bareItem, parseParameters()));
+                char c = peek();
+                if (c != ' ' && c != ')')
+                    throw error("Expected ' ' or ')'");
+            }
+        }
+
+        private Map<String, Object> parseParameters()
+        {
+            Map<String, Object> parameters = new LinkedHashMap<>();
+            while (consume(';'))
+            {
+                skipSpaces();
+                String key = parseKey();
+                Object parameter = Boolean.TRUE;
+                if (consume('='))
+                    parameter = parseBareItem();
+                parameters.put(key, parameter);
+            }
+            return parameters;
+        }
+
+        private Object parseBareItem()
+        {
+            char c = peek();
+            if (c == '-' || c >= '0' && c <= '9')
+                return parseInteger();
+            if (c == '"')
+                return parseString();
+            if (c == ':')
+                return parseByteSequence();
+            if (c == '?')
+                return parseBoolean();
+            if (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*')
+                return parseToken();
+            throw error("Invalid item");
+        }
+
+        private Long parseInteger()
+        {
+            int start = index;
+            consume('-');
+            int digits = 0;
+            while (peek() >= '0' && peek() <= '9')
+            {
+                ++index;
+                ++digits;
+            }
+            if (digits == 0 || digits > 15)
+                throw error("Invalid integer");
+            if (peek() == '.')
+                throw error("Unsupported decimal");
+            return Long.parseLong(value.substring(start, index));
+        }
+
+        private String parseString()
+        {
+            consume('"');
+            StringBuilder builder = new StringBuilder();
+            while (!isEnd())
+            {
+                char c = value.charAt(index++);
+                if (c == '"')
+                    return builder.toString();
+                if (c == '\\')
+                {
+                    char next = peek();
+                    if (next != '"' && next != '\\')
+                        throw error("Invalid escape");
+                    ++index;
+                    builder.append(next);
+                }
+                else if (c < 0x20 || c >= 0x7F)
+                {
+                    throw error("Invalid string character");
+                }
+                else
+                {
+                    builder.append(c);
+                }
+            }
+            throw error("Unterminated string");
+        }
+
+        private byte[] parseByteSequence()
+        {
+            consume(':');
+            int end = value.indexOf(':', index);
+            if (end < 0)
+                throw error("Unterminated byte sequence");
+            String base64 = value.substring(index, end);
+            index = end + 1;
+            try
+            {
+                return Base64.getDecoder().decode(base64);
+            }
+            catch (IllegalArgumentException x)
+            {
+                throw error("Invalid byte sequence");
+            }
+        }
+
+        private Boolean parseBoolean()
+        {
+            consume('?');
+            if (consume('1'))
+                return Boolean.TRUE;
+            if (consume('0'))
+                return Boolean.FALSE;
+            throw error("Invalid boolean");
+        }
+
+        private Token parseToken()
+        {
+            int start = index;
+            ++index;
+            while (!isEnd())
+            {
+                char c = peek();
+                if (c > 0x20 && c < 0x7F && "\"(),;<=>?@[\\]{}".indexOf(c) < 0)
+                    ++index;
+                else
+                    break;
+            }
+            return new Token(value.substring(start, index));
+        }
+    }
+}
@@ -0,0 +1,274 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.nio.charset.StandardCharsets;
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.SignatureException;
+import java.security.spec.ECGenParameterSpec;
+import java.util.Base64;
+import java.util.List;
+import java.util.Map;
+import java.util.stream.Stream;
+import javax.crypto.spec.SecretKeySpec;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpURI;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.condition.EnabledForJreRange;
+import org.junit.jupiter.api.condition.JRE;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.MethodSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.startsWith;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class HttpMessageSignatureTest
+{
+    private static final HttpURI URI = HttpURI.from("https://example.com/foo?param=Value&Pet=dog");
+
+    private static HttpFields.Mutable newHeaders()
+    {
+        return HttpFields.build()
+            .add("Host", "example.com")
+            .add("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+            .add("Content-Type", "application/json")
+            .add("Content-Length", "18");
+    }
+
+    @Test
+    public void testSignatureBase() throws Exception
+    {
+        HttpURI uri = HttpURI.from("https://www.Example.com:443/path?param=value&foo=bar&baz=bat%20man&qux=");
+        HttpFields headers = HttpFields.build()
+            .add("X-Multi", " a ")
+            .add("X-Multi", "b");
+        SignatureInput input = new SignatureInput(List.of(
+            SignatureComponent.METHOD,
+            SignatureComponent.TARGET_URI,
+            SignatureComponent.AUTHORITY,
+            SignatureComponent.SCHEME,
+            SignatureComponent.REQUEST_TARGET,
+            SignatureComponent.PATH,
+            SignatureComponent.QUERY,
+            SignatureComponent.queryParam("baz"),
+            SignatureComponent.queryParam("qux"),
+            SignatureComponent.of("X-Multi")
+        ), Map.of(SignatureInput.KEY_ID, "test"));
+
+        String base = SignatureBase.build("POST", uri, headers, input);
+
+        assertThat(base, is(
+            "\"@method\": POST\n" +
+            "\"@target-uri\": https://www.example.com/path?param=value&foo=bar&baz=bat%20man&qux=\n" +
+            "\"@authority\": www.example.com\n" +
+            "\"@scheme\": https\n" +
+            "\"@request-target\": /path?param=value&foo=bar&baz=bat%20man&qux=\n" +
+            "\"@path\": /path\n" +
+            "\"@query\": ?param=value&foo=bar&baz=bat%20man&qux=\n" +
+            "\"@query-param\";name=\"baz\": bat%20man\n" +
+            "\"@query-param\";name=\"qux\": \n" +
+            "\"x-multi\": a, b\n" +
+            "\"@signature-params\": (\"@method\" \"@target-uri\" \"@authority\" \"@scheme\" \"@request-target\" \"@path\" \"@query\" " +
+            "\"@query-param\";name=\"baz\" \"@query-param\";name=\"qux\" \"x-multi\");keyid=\"test\""));
+
+        SignatureInput missing = new SignatureInput(List.of(SignatureComponent.of("digest")), Map.of());
+        assertThrows(SignatureException.class, () -> SignatureBase.build("POST", uri, headers, missing));
+
+        HttpURI malformed = HttpURI.from("https://www.example.com/path?baz=%zz");
+        SignatureInput query = new SignatureInput(List.of(SignatureComponent.queryParam("baz")), Map.of());
+        assertThrows(SignatureException.class, () -> SignatureBase.build("POST", malformed, headers, query));
+    }
+
+    @Test
+    public void testVerifyRFC9421SharedSecretExample() throws Exception
+    {
+        // RFC 9421, appendix B.2.5.
+        byte[] secret = Base64.getDecoder().decode("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==");
+        SignatureKey key = new SignatureKey("test-shared-secret", SignatureAlgorithm.HMAC_SHA256, new SecretKeySpec(secret, "HmacSHA256"));
+        HttpFields headers = newHeaders()
+            .add("Signature-Input", "sig-b25=(\"date\" \"@authority\" \"content-type\");created=1618884473;keyid=\"test-shared-secret\"")
+            .add("Signature", "sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:");
+
+        HttpMessageVerifier verifier = new HttpMessageVerifier(SignatureKeyResolver.of(key));
+        HttpMessageVerifier.VerifiedSignature signature = verifier.verify("POST", URI, headers);
+        assertThat(signature.getLabel(), is("sig-b25"));
+        assertThat(signature.getKey(), is(key));
+        assertThat(signature.getInput().getCreated(), is(1618884473L));
+
+        // The example is too old for a verifier with a max age.
+        verifier.setMaxAge(300);
+        assertThrows(SignatureException.class, () -> verifier.verify("POST", URI, headers));
+    }
+
+    public static Stream<SignatureKey[]> keys() throws Exception
+    {
+        KeyPairGenerator rsa = KeyPairGenerator.getInstance("RSA");
+        rsa.initialize(2048);
+        KeyPair rsaKeys = rsa.generateKeyPair();
+        KeyPair p256Keys = ecKeyPair("secp256r1");
+        KeyPair p384Keys = ecKeyPair("secp384r1");
+        SecretKeySpec secret = new SecretKeySpec(new byte[64], "HmacSHA256");
+        return Stream.of(
+            new SignatureKey[]{
+                new SignatureKey("rsa-pss", SignatureAlgorithm.RSA_PSS_SHA512, rsaKeys.getPrivate()),
+                new SignatureKey("rsa-pss", SignatureAlgorithm.RSA_PSS_SHA512, rsaKeys.getPublic())
+            },
+            new SignatureKey[]{
+                new SignatureKey("rsa", SignatureAlgorithm.RSA_V1_5_SHA256, rsaKeys.getPrivate()),
+                new SignatureKey("rsa", SignatureAlgorithm.RSA_V1_5_SHA256, rsaKeys.getPublic())
+            },
+            new SignatureKey[]{
+                new SignatureKey("p256", SignatureAlgorithm.ECDSA_P256_SHA256, p256Keys.getPrivate()),
+                new SignatureKey("p256", SignatureAlgorithm.ECDSA_P256_SHA256, p256Keys.getPublic())
+            },
+            new SignatureKey[]{
+                new SignatureKey("p384", SignatureAlgorithm.ECDSA_P384_SHA384, p384Keys.getPrivate()),
+                new SignatureKey("p384", SignatureAlgorithm.ECDSA_P384_SHA384, p384Keys.getPublic())
+            },
+            new SignatureKey[]{
+                new SignatureKey("hmac", SignatureAlgorithm.HMAC_SHA256, secret),
+                new SignatureKey("hmac", SignatureAlgorithm.HMAC_SHA256, secret)
+            }
+        );
+    }
+
+    private static KeyPair ecKeyPair(String curve) throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("EC");
+        generator.initialize(new ECGenParameterSpec(curve));
+        return generator.generateKeyPair();
+    }
+
+    @ParameterizedTest
+    @MethodSource("keys")
+    public void testSignAndVerify(SignatureKey signingKey, SignatureKey verifyingKey) throws Exception
+    {
+        testSignAndVerify(signingKey, SignatureKeyResolver.of(verifyingKey));
+    }
+
+    @Test
+    @EnabledForJreRange(min = JRE.JAVA_15)
+    public void testSignAndVerifyEd25519() throws Exception
+    {
+        KeyPair keys = KeyPairGenerator.getInstance("Ed25519").generateKeyPair();
+        testSignAndVerify(new SignatureKey("ed", SignatureAlgorithm.ED25519, keys.getPrivate()),
+            SignatureKeyResolver.of(new SignatureKey("ed", SignatureAlgorithm.ED25519, keys.getPublic())));
+    }
+
+    private void testSignAndVerify(SignatureKey signingKey, SignatureKeyResolver resolver) throws Exception
+    {
+        HttpMessageSigner signer = new HttpMessageSigner(signingKey,
+            SignatureComponent.METHOD, SignatureComponent.TARGET_URI, SignatureComponent.queryParam("Pet"), SignatureComponent.of("content-type"));
+        signer.setExpiresIn(60);
+        signer.setNonce(true);
+        signer.setIncludeAlgorithm(true);
+        signer.setTag("test");
+        HttpFields.Mutable headers = newHeaders();
+        HttpFields signatureFields = signer.sign("POST", URI, headers);
+        assertThat(signatureFields.get("Signature-Input"), startsWith("sig1=(\"@method\" \"@target-uri\" \"@query-param\";name=\"Pet\" \"content-type\");created="));
+        assertThat(signatureFields.get("Signature-Input"), containsString(";alg=\"" + signingKey.getAlgorithm().getName() + "\";keyid=\"" + signingKey.getKeyId() + "\";tag=\"test\""));
+        headers.add(signatureFields);
+
+        HttpMessageVerifier verifier = new HttpMessageVerifier(resolver);
+        verifier.setRequiredComponents(List.of(SignatureComponent.METHOD, SignatureComponent.TARGET_URI));
+        verifier.setMaxAge(60);
+        verifier.setExpiresRequired(true);
+        HttpMessageVerifier.VerifiedSignature signature = verifier.verify("POST", URI, headers);
+        assertThat(signature.getLabel(), is(HttpMessageSigner.DEFAULT_LABEL));
+        assertThat(signature.getInput().getTag(), is("test"));
+
+        // A different method, URI or covered header fails verification.
+        assertThrows(SignatureException.class, () -> verifier.verify("PUT", URI, headers));
+        assertThrows(SignatureException.class, () -> verifier.verify("POST", HttpURI.from("https://example.com/foo?param=Value&Pet=cat"), headers));
+        HttpFields.Mutable tampered = HttpFields.build(headers).put("Content-Type", "text/plain");
+        assertThrows(SignatureException.class, () -> verifier.verify("POST", URI, tampered));
+
+        // Headers that are not covered may change.
+        HttpFields.Mutable notCovered = HttpFields.build(headers).put("Date", "Wed, 21 Apr 2021 02:07:55 GMT");
+        verifier.verify("POST", URI, notCovered);
+
+        // Required components must be covered.
+        verifier.setRequiredComponents(List.of(SignatureComponent.of("date")));
+        assertThrows(SignatureException.class, () -> verifier.verify("POST", URI, headers));
+    }
+
+    @Test
+    public void testTimesAndKeys() throws Exception
+    {
+        SecretKeySpec secret = new SecretKeySpec(new byte[32], "HmacSHA256");
+        SignatureKey key = new SignatureKey("hmac", SignatureAlgorithm.HMAC_SHA256, secret);
+        HttpMessageVerifier verifier = new HttpMessageVerifier(SignatureKeyResolver.of(key));
+
+        long now = System.currentTimeMillis() / 1000;
+        assertThat(verify(verifier, key, "created=" + now), is(true));
+        // Created in the future, beyond the clock skew.
+        assertThat(verify(verifier, key, "created=" + (now + 3600)), is(false));
+        // Expired.
+        assertThat(verify(verifier, key, "created=" + (now - 120) + ";expires=" + (now - 60)), is(false));
+        // Missing created.
+        assertThat(verify(verifier, key, "expires=" + (now + 60)), is(false));
+        verifier.setCreatedRequired(false);
+        assertThat(verify(verifier, key, "expires=" + (now + 60)), is(true));
+        // Algorithm not matching the key.
+        assertThat(verify(verifier, key, "created=" + now + ";alg=\"ed25519\""), is(false));
+        // Unknown key.
+        assertThat(verify(verifier, new SignatureKey("other", SignatureAlgorithm.HMAC_SHA256, secret), "created=" + now), is(false));
+    }
+
+    private boolean verify(HttpMessageVerifier verifier, SignatureKey key, String parameters) throws Exception
+    {
+        SignatureInput input = SignatureInput.parse("sig=(\"@method\");" + parameters + ";keyid=\"" + key.getKeyId() + "\"").get("sig");
+        String base = SignatureBase.build("GET", URI, HttpFields.EMPTY, input);
+        byte[] signature = key.getAlgorithm().sign(key.getKey(), base.getBytes(StandardCharsets.US_ASCII));
+        HttpFields headers = HttpFields.build()
+            .add("Signature-Input", "sig=" + input.asString())
+            .add("Signature", "sig=:" + Base64.getEncoder().encodeToString(signature) + ":");
+        try
+        {
+            verifier.verify("GET", URI, headers);
+            return true;
+        }
+        catch (SignatureException x)
+        {
+            return false;
+        }
+    }
+
+    @Test
+    public void testMultipleSignatures() throws Exception
+    {
+        SignatureKey key1 = new SignatureKey("key1", SignatureAlgorithm.HMAC_SHA256, new SecretKeySpec(new byte[32], "HmacSHA256"));
+        SignatureKey key2 = new SignatureKey("key2", SignatureAlgorithm.HMAC_SHA256, new SecretKeySpec(new byte[]{1, 2, 3, 4}, "HmacSHA256"));
+        HttpMessageSigner signer1 = new HttpMessageSigner(key1, SignatureComponent.METHOD);
+        HttpMessageSigner signer2 = new HttpMessageSigner(key2, SignatureComponent.METHOD, SignatureComponent.PATH);
+        signer2.setLabel("proxy");
+        HttpFields.Mutable headers = HttpFields.build();
+        headers.add(signer1.sign("GET", URI, headers));
+        headers.add(signer2.sign("GET", URI, headers));
+
+        // Only the second key is known.
+        HttpMessageVerifier verifier = new HttpMessageVerifier(SignatureKeyResolver.of(key2));
+        assertThat(verifier.verify("GET", URI, headers).getLabel(), is("proxy"));
+        verifier.setLabel("sig1");
+        assertThrows(SignatureException.class, () -> verifier.verify("GET", URI, headers));
+
+        assertThrows(SignatureException.class, () -> verifier.verify("GET", URI, HttpFields.EMPTY));
+        assertThrows(IllegalArgumentException.class, () -> signer1.setLabel("Invalid Label"));
+    }
+}
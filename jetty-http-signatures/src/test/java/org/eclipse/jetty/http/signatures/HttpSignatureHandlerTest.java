@@ -0,0 +1,162 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.signatures;
+
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.SignatureException;
+import java.security.spec.ECGenParameterSpec;
+import java.util.List;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.util.StringRequestContent;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class HttpSignatureHandlerTest
+{
+    private Server server;
+    private ServerConnector connector;
+    private HttpClient client;
+    private HttpSignatureHandler signatureHandler;
+    private SignatureKey privateKey;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("EC");
+        generator.initialize(new ECGenParameterSpec("secp256r1"));
+        KeyPair keys = generator.generateKeyPair();
+        privateKey = new SignatureKey("client", SignatureAlgorithm.ECDSA_P256_SHA256, keys.getPrivate());
+        SignatureKey publicKey = new SignatureKey("client", SignatureAlgorithm.ECDSA_P256_SHA256, keys.getPublic());
+
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+        HttpMessageVerifier verifier = new HttpMessageVerifier(SignatureKeyResolver.of(publicKey));
+        verifier.setRequiredComponents(List.of(SignatureComponent.METHOD, SignatureComponent.TARGET_URI));
+        verifier.setMaxAge(60);
+        signatureHandler = new HttpSignatureHandler(verifier);
+        signatureHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                HttpMessageVerifier.VerifiedSignature signature = (HttpMessageVerifier.VerifiedSignature)request.getAttribute(HttpSignatureHandler.VERIFIED_SIGNATURE_ATTRIBUTE);
+                if (signature != null)
+                    response.setHeader("X-Key-Id", signature.getKey().getKeyId());
+            }
+        });
+        server.setHandler(signatureHandler);
+        server.start();
+
+        client = new HttpClient();
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (server != null)
+            server.stop();
+    }
+
+    @Test
+    public void testSignedRequest() throws Exception
+    {
+        HttpMessageSigner signer = new HttpMessageSigner(privateKey,
+            SignatureComponent.METHOD, SignatureComponent.TARGET_URI, SignatureComponent.of("content-type"), SignatureComponent.of("content-length"));
+        client.addInterceptor(new HttpSignatureInterceptor(signer));
+
+        // The Content-Type and Content-Length headers are added by
+        // HttpClient, but they are covered by the signature anyway.
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .method(HttpMethod.POST)
+            .path("/path?a=1")
+            .body(new StringRequestContent("text/plain", "hello"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getHeaders().get("X-Key-Id"), is("client"));
+        assertThat(signatureHandler.getVerifiedRequests(), is(1L));
+    }
+
+    @Test
+    public void testUnsignedRequest() throws Exception
+    {
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(signatureHandler.getRejectedRequests(), is(1L));
+
+        // Requests without signatures are allowed, but not requests with invalid signatures.
+        signatureHandler.setSignatureRequired(false);
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .headers(headers -> headers
+                .put("Signature-Input", "sig1=(\"@method\" \"@target-uri\");created=" + System.currentTimeMillis() / 1000 + ";keyid=\"client\"")
+                .put("Signature", "sig1=:AAAA:"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+    }
+
+    @Test
+    public void testRequestNotCoveringRequiredComponents() throws Exception
+    {
+        client.addInterceptor(new HttpSignatureInterceptor(new HttpMessageSigner(privateKey, SignatureComponent.METHOD)));
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+    }
+
+    @Test
+    public void testRequestMissingCoveredHeaderIsAborted()
+    {
+        client.addInterceptor(new HttpSignatureInterceptor(new HttpMessageSigner(privateKey, SignatureComponent.METHOD, SignatureComponent.of("digest"))));
+
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send());
+        assertThat(failure.getCause(), instanceOf(SignatureException.class));
+    }
+}
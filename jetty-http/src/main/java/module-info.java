@@ -21,6 +21,7 @@ module org.eclipse.jetty.http
     exports org.eclipse.jetty.http.compression;
     exports org.eclipse.jetty.http.pathmap;
 
+    uses org.eclipse.jetty.http.ContentCodec;
     uses org.eclipse.jetty.http.HttpFieldPreEncoder;
 
     provides org.eclipse.jetty.http.HttpFieldPreEncoder with
@@ -0,0 +1,241 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Queue;
+import java.util.concurrent.Executor;
+
+import org.eclipse.jetty.io.AbstractConnection;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link org.eclipse.jetty.io.Connection} that exchanges
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9297#section-3.2">capsules</a>
+ * over the tunnel of an Extended CONNECT request.</p>
+ * <p>The content read from the tunnel is parsed with a {@link CapsuleParser}, and the
+ * {@link Listener} is notified of each capsule; capsules are sent with
+ * {@link #send(long, ByteBuffer, Callback)}, and are written to the tunnel in order.</p>
+ * <p>On the server, a {@code CapsuleConnection} is installed on the tunnel with
+ * {@code HttpChannel.upgradeToCapsules(...)}; on the client, with
+ * {@code CapsuleResponseListener}.</p>
+ */
+public class CapsuleConnection extends AbstractConnection
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CapsuleConnection.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final Queue<Entry> _entries = new ArrayDeque<>();
+    private final Flusher _flusher = new Flusher();
+    private final ByteBufferPool _byteBufferPool;
+    private final Listener _listener;
+    private final CapsuleParser _parser;
+    private boolean _terminated;
+
+    /**
+     * @param endPoint the tunnel endpoint
+     * @param executor the executor
+     * @param byteBufferPool the pool of the buffers used to read from the tunnel
+     * @param listener the listener notified of the capsules read from the tunnel
+     * @param maxCapsuleLength the max capsule value length, in bytes
+     */
+    public CapsuleConnection(EndPoint endPoint, Executor executor, ByteBufferPool byteBufferPool, Listener listener, int maxCapsuleLength)
+    {
+        super(endPoint, executor);
+        _byteBufferPool = byteBufferPool;
+        _listener = listener;
+        _parser = new CapsuleParser(listener, maxCapsuleLength);
+    }
+
+    @Override
+    public void onOpen()
+    {
+        super.onOpen();
+        fillInterested();
+    }
+
+    @Override
+    public void onFillable()
+    {
+        ByteBuffer buffer = _byteBufferPool.acquire(getInputBufferSize(), true);
+        try
+        {
+            while (true)
+            {
+                int filled = getEndPoint().fill(buffer);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("filled {} on {}", filled, this);
+                if (filled > 0)
+                {
+                    _parser.parse(buffer);
+                }
+                else if (filled == 0)
+                {
+                    fillInterested();
+                    break;
+                }
+                else
+                {
+                    // The other peer ended the tunnel.
+                    getEndPoint().close(_parser.isComplete() ? null : new IOException("Truncated capsule"));
+                    break;
+                }
+            }
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("could not read from {}", this, x);
+            getEndPoint().close(x);
+        }
+        finally
+        {
+            _byteBufferPool.release(buffer);
+        }
+    }
+
+    /**
+     * <p>Sends a capsule with the given type and value.</p>
+     * <p>The value is copied, so the buffer may be reused as soon as this method returns.</p>
+     *
+     * @param type the capsule type
+     * @param value the capsule value
+     * @param callback the callback completed when the capsule has been written to the tunnel
+     */
+    public void send(long type, ByteBuffer value, Callback callback)
+    {
+        send(CapsuleGenerator.generate(type, value), callback);
+    }
+
+    /**
+     * <p>Sends an already generated capsule, for example a
+     * {@link CapsuleGenerator#generateDatagram(long, ByteBuffer) DATAGRAM capsule}.</p>
+     *
+     * @param capsule the encoded capsule, that must not be modified until the callback is completed
+     * @param callback the callback completed when the capsule has been written to the tunnel
+     */
+    public void send(ByteBuffer capsule, Callback callback)
+    {
+        boolean closed;
+        try (AutoLock l = _lock.lock())
+        {
+            closed = _terminated;
+            if (!closed)
+                _entries.offer(new Entry(capsule, callback));
+        }
+        if (closed)
+            callback.failed(new ClosedChannelException());
+        else
+            _flusher.iterate();
+    }
+
+    @Override
+    public void onClose(Throwable cause)
+    {
+        super.onClose(cause);
+        List<Entry> pending;
+        try (AutoLock l = _lock.lock())
+        {
+            _terminated = true;
+            pending = new ArrayList<>(_entries);
+            _entries.clear();
+        }
+        Throwable failure = cause == null ? new ClosedChannelException() : cause;
+        pending.forEach(entry -> entry._callback.failed(failure));
+        try
+        {
+            _listener.onClose(cause);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", _listener, x);
+        }
+    }
+
+    /**
+     * <p>A listener for the capsules read from the tunnel, and for the end of the tunnel.</p>
+     */
+    public interface Listener extends CapsuleParser.Listener
+    {
+        /**
+         * <p>Callback method invoked when the tunnel is closed.</p>
+         *
+         * @param failure the failure that caused the tunnel to close,
+         * or {@code null} if the tunnel has been ended normally
+         */
+        default void onClose(Throwable failure)
+        {
+        }
+    }
+
+    private class Flusher extends IteratingCallback
+    {
+        private Entry _entry;
+
+        @Override
+        protected Action process()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                _entry = _entries.poll();
+            }
+            if (_entry == null)
+                return Action.IDLE;
+            getEndPoint().write(this, _entry._capsule);
+            return Action.SCHEDULED;
+        }
+
+        @Override
+        public void succeeded()
+        {
+            Entry entry = _entry;
+            _entry = null;
+            entry._callback.succeeded();
+            super.succeeded();
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable cause)
+        {
+            Entry entry = _entry;
+            _entry = null;
+            if (entry != null)
+                entry._callback.failed(cause);
+            getEndPoint().close(cause);
+        }
+    }
+
+    private static class Entry
+    {
+        private final ByteBuffer _capsule;
+        private final Callback _callback;
+
+        private Entry(ByteBuffer capsule, Callback callback)
+        {
+            _capsule = capsule;
+            _callback = callback;
+        }
+    }
+}
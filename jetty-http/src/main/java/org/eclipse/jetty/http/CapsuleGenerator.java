@@ -0,0 +1,124 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.util.BufferUtil;
+
+/**
+ * <p>A generator for the
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9297#section-3.2">Capsule Protocol</a>.</p>
+ *
+ * @see CapsuleParser
+ */
+public class CapsuleGenerator
+{
+    /**
+     * <p>Generates a capsule with the given type and value.</p>
+     *
+     * @param type the capsule type
+     * @param value the capsule value, that is not consumed by this method
+     * @return a buffer in flush mode containing the encoded capsule
+     */
+    public static ByteBuffer generate(long type, ByteBuffer value)
+    {
+        int length = value == null ? 0 : value.remaining();
+        ByteBuffer buffer = BufferUtil.allocate(length(type, length));
+        BufferUtil.flipToFill(buffer);
+        generate(buffer, type, value);
+        BufferUtil.flipToFlush(buffer, 0);
+        return buffer;
+    }
+
+    /**
+     * <p>Generates a capsule with the given type and value into the given buffer,
+     * which must be in fill mode and have enough space (see {@link #length(long, int)}).</p>
+     *
+     * @param buffer the buffer to generate into
+     * @param type the capsule type
+     * @param value the capsule value, that is not consumed by this method
+     */
+    public static void generate(ByteBuffer buffer, long type, ByteBuffer value)
+    {
+        int length = value == null ? 0 : value.remaining();
+        encodeVarInt(buffer, type);
+        encodeVarInt(buffer, length);
+        if (length > 0)
+            buffer.put(value.slice());
+    }
+
+    /**
+     * <p>Generates a {@link CapsuleParser#DATAGRAM DATAGRAM} capsule whose value is made
+     * of the given context ID followed by the given payload, as specified by
+     * <a href="https://datatracker.ietf.org/doc/html/rfc9298#section-4">RFC 9298</a>.</p>
+     *
+     * @param contextId the context ID, {@code 0} for UDP payloads
+     * @param payload the HTTP datagram payload, that is not consumed by this method
+     * @return a buffer in flush mode containing the encoded capsule
+     * @see CapsuleParser#decodeVarInt(ByteBuffer)
+     */
+    public static ByteBuffer generateDatagram(long contextId, ByteBuffer payload)
+    {
+        int payloadLength = payload == null ? 0 : payload.remaining();
+        int valueLength = varIntLength(contextId) + payloadLength;
+        ByteBuffer buffer = BufferUtil.allocate(length(CapsuleParser.DATAGRAM, valueLength));
+        BufferUtil.flipToFill(buffer);
+        encodeVarInt(buffer, CapsuleParser.DATAGRAM);
+        encodeVarInt(buffer, valueLength);
+        encodeVarInt(buffer, contextId);
+        if (payloadLength > 0)
+            buffer.put(payload.slice());
+        BufferUtil.flipToFlush(buffer, 0);
+        return buffer;
+    }
+
+    /**
+     * @param type the capsule type
+     * @param valueLength the capsule value length
+     * @return the number of bytes needed to encode the capsule
+     */
+    public static int length(long type, int valueLength)
+    {
+        return varIntLength(type) + varIntLength(valueLength) + valueLength;
+    }
+
+    static void encodeVarInt(ByteBuffer buffer, long value)
+    {
+        int length = varIntLength(value);
+        int encoding = 31 - Integer.numberOfLeadingZeros(length);
+        for (int i = length - 1; i >= 0; --i)
+        {
+            long b = (value >>> (8 * i)) & 0xFF;
+            if (i == length - 1)
+                b = b | (encoding << 6);
+            buffer.put((byte)b);
+        }
+    }
+
+    static int varIntLength(long value)
+    {
+        if (value < 0)
+            throw new IllegalArgumentException("Invalid variable length integer " + value);
+        if (value < (1 << 6))
+            return 1;
+        if (value < (1 << 14))
+            return 2;
+        if (value < (1 << 30))
+            return 4;
+        if (value < (1L << 62))
+            return 8;
+        throw new IllegalArgumentException("Invalid variable length integer " + value);
+    }
+}
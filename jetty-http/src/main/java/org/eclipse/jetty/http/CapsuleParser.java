@@ -0,0 +1,228 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.util.BufferUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A parser for the
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9297#section-3.2">Capsule Protocol</a>.</p>
+ * <p>Capsules are carried in the content of an Extended CONNECT tunnel, and are
+ * encoded as a sequence of {@code Type (i), Length (i), Value (..)} tuples where
+ * the type and the length are QUIC variable length integers.</p>
+ * <p>The parser may be fed with arbitrarily fragmented buffers: for every complete
+ * capsule the {@link Listener} is notified with the capsule type and value.
+ * After the tunnel content has been fully consumed, {@link #isComplete()} must
+ * be checked to detect a truncated capsule.</p>
+ * <p>Applications typically do not use the parser directly, but exchange capsules with a
+ * {@link CapsuleConnection}, installed on the tunnel with {@code HttpChannel.upgradeToCapsules(...)}
+ * on the server and with {@code CapsuleResponseListener} on the client.
+ * Tunnel endpoints that process the tunnel content themselves, like {@code ConnectHandler}
+ * in jetty-proxy, feed it to a parser:</p>
+ * <pre>{@code
+ * CapsuleParser parser = new CapsuleParser((type, value) -> process(type, value));
+ * // For each chunk of tunnel content.
+ * parser.parse(content);
+ * // At the end of the tunnel content.
+ * if (!parser.isComplete())
+ *     throw new EOFException("truncated capsule");
+ * }</pre>
+ *
+ * @see CapsuleGenerator
+ */
+public class CapsuleParser
+{
+    /**
+     * The {@code DATAGRAM} capsule type.
+     */
+    public static final long DATAGRAM = 0x00;
+
+    private static final Logger LOG = LoggerFactory.getLogger(CapsuleParser.class);
+
+    private final Listener _listener;
+    private final int _maxCapsuleLength;
+    private State _state = State.TYPE;
+    private int _varIntLength;
+    private long _varInt;
+    private long _type;
+    private ByteBuffer _value;
+
+    public CapsuleParser(Listener listener)
+    {
+        this(listener, 65536);
+    }
+
+    /**
+     * @param listener the listener notified of parsed capsules
+     * @param maxCapsuleLength the max capsule value length, in bytes
+     */
+    public CapsuleParser(Listener listener, int maxCapsuleLength)
+    {
+        _listener = listener;
+        _maxCapsuleLength = maxCapsuleLength;
+    }
+
+    /**
+     * @return the max capsule value length, in bytes
+     */
+    public int getMaxCapsuleLength()
+    {
+        return _maxCapsuleLength;
+    }
+
+    /**
+     * @return whether the parser is at a capsule boundary, i.e. it is not in the middle of a capsule
+     */
+    public boolean isComplete()
+    {
+        return _state == State.TYPE && _varIntLength == 0;
+    }
+
+    /**
+     * <p>Parses the given buffer, notifying the listener for each complete capsule.</p>
+     *
+     * @param buffer the buffer to parse
+     * @throws IllegalArgumentException if a capsule is larger than the max capsule length
+     */
+    public void parse(ByteBuffer buffer)
+    {
+        while (buffer.hasRemaining())
+        {
+            switch (_state)
+            {
+                case TYPE:
+                {
+                    if (parseVarInt(buffer))
+                    {
+                        _type = _varInt;
+                        _state = State.LENGTH;
+                    }
+                    break;
+                }
+                case LENGTH:
+                {
+                    if (parseVarInt(buffer))
+                    {
+                        long length = _varInt;
+                        if (length > _maxCapsuleLength)
+                            throw new IllegalArgumentException("Capsule too large: " + length + " > " + _maxCapsuleLength);
+                        _value = BufferUtil.allocate((int)length);
+                        BufferUtil.flipToFill(_value);
+                        if (length == 0)
+                            notifyCapsule();
+                        else
+                            _state = State.VALUE;
+                    }
+                    break;
+                }
+                case VALUE:
+                {
+                    int length = Math.min(buffer.remaining(), _value.remaining());
+                    ByteBuffer slice = buffer.slice();
+                    slice.limit(length);
+                    _value.put(slice);
+                    buffer.position(buffer.position() + length);
+                    if (!_value.hasRemaining())
+                        notifyCapsule();
+                    break;
+                }
+                default:
+                {
+                    throw new IllegalStateException(_state.toString());
+                }
+            }
+        }
+    }
+
+    /**
+     * <p>Decodes a QUIC variable length integer from the given buffer,
+     * for example the context ID at the beginning of the value of a
+     * {@link #DATAGRAM} capsule.</p>
+     *
+     * @param buffer the buffer to decode from, consumed only if the integer is complete
+     * @return the decoded integer, or {@code -1} if the buffer does not contain a complete integer
+     */
+    public static long decodeVarInt(ByteBuffer buffer)
+    {
+        if (!buffer.hasRemaining())
+            return -1;
+        int position = buffer.position();
+        int length = 1 << ((buffer.get(position) & 0xC0) >>> 6);
+        if (buffer.remaining() < length)
+            return -1;
+        long value = buffer.get() & 0x3F;
+        for (int i = 1; i < length; ++i)
+        {
+            value = (value << 8) + (buffer.get() & 0xFF);
+        }
+        return value;
+    }
+
+    private boolean parseVarInt(ByteBuffer buffer)
+    {
+        if (_varIntLength == 0)
+        {
+            // The 2 most significant bits of the first byte hold the encoding.
+            byte hiByte = buffer.get();
+            _varIntLength = 1 << ((hiByte & 0xC0) >>> 6);
+            _varInt = hiByte & 0x3F;
+            return --_varIntLength == 0;
+        }
+        _varInt = (_varInt << 8) + (buffer.get() & 0xFF);
+        return --_varIntLength == 0;
+    }
+
+    private void notifyCapsule()
+    {
+        long type = _type;
+        ByteBuffer value = _value;
+        BufferUtil.flipToFlush(value, 0);
+        _state = State.TYPE;
+        _type = 0;
+        _value = null;
+        if (LOG.isDebugEnabled())
+            LOG.debug("parsed capsule type={} length={} on {}", type, value.remaining(), this);
+        _listener.onCapsule(type, value);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), _state);
+    }
+
+    private enum State
+    {
+        TYPE, LENGTH, VALUE
+    }
+
+    /**
+     * <p>A listener for parsed capsules.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Callback method invoked when a complete capsule has been parsed.</p>
+         * <p>Unknown capsule types must be ignored, as specified by RFC 9297.</p>
+         *
+         * @param type the capsule type
+         * @param value the capsule value
+         */
+        void onCapsule(long type, ByteBuffer value);
+    }
+}
@@ -80,8 +80,93 @@ public interface ComplianceViolation
      */
     interface Listener
     {
+        /**
+         * <p>Invoked when a violation is allowed by the mode.</p>
+         *
+         * @param mode the compliance mode
+         * @param violation the violation
+         * @param details the details of the violation
+         */
         default void onComplianceViolation(Mode mode, ComplianceViolation violation, String details)
         {
         }
+
+        /**
+         * <p>Invoked for every violation detected, both allowed and not allowed by the mode.</p>
+         * <p>Violations that are not allowed cause the message to be rejected; this method may
+         * be overridden to observe, count or log rejected messages, for example to detect
+         * request smuggling attempts.</p>
+         * <p>The default implementation forwards allowed violations to
+         * {@link #onComplianceViolation(Mode, ComplianceViolation, String)}.</p>
+         *
+         * @param event the violation event
+         */
+        default void onComplianceViolation(Event event)
+        {
+            if (event.isAllowed())
+                onComplianceViolation(event.getMode(), event.getViolation(), event.getDetails());
+        }
+    }
+
+    /**
+     * <p>A structured representation of a violation detected while parsing a message.</p>
+     */
+    class Event
+    {
+        private final Mode mode;
+        private final ComplianceViolation violation;
+        private final String details;
+        private final boolean allowed;
+
+        public Event(Mode mode, ComplianceViolation violation, String details)
+        {
+            this(mode, violation, details, mode.allows(violation));
+        }
+
+        public Event(Mode mode, ComplianceViolation violation, String details, boolean allowed)
+        {
+            this.mode = mode;
+            this.violation = violation;
+            this.details = details;
+            this.allowed = allowed;
+        }
+
+        /**
+         * @return the compliance mode in use when the violation was detected
+         */
+        public Mode getMode()
+        {
+            return mode;
+        }
+
+        /**
+         * @return the violation
+         */
+        public ComplianceViolation getViolation()
+        {
+            return violation;
+        }
+
+        /**
+         * @return the details of the violation
+         */
+        public String getDetails()
+        {
+            return details;
+        }
+
+        /**
+         * @return whether the violation was allowed, or the message was rejected
+         */
+        public boolean isAllowed()
+        {
+            return allowed;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s,%s,%s,%s}", getClass().getSimpleName(), hashCode(), mode.getName(), violation.getName(), allowed ? "allowed" : "rejected", details);
+        }
     }
 }
@@ -31,6 +31,7 @@ public class CompressedContentFormat
 
     public static final CompressedContentFormat GZIP = new CompressedContentFormat("gzip", ".gz");
     public static final CompressedContentFormat BR = new CompressedContentFormat("br", ".br");
+    public static final CompressedContentFormat ZSTD = new CompressedContentFormat("zstd", ".zst");
     public static final CompressedContentFormat[] NONE = new CompressedContentFormat[0];
 
     private final String _encoding;
@@ -0,0 +1,242 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.ServiceLoader;
+
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.TypeUtil;
+import org.eclipse.jetty.util.component.Destroyable;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A content coding, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9110#section-8.4.1">RFC 9110, section 8.4.1</a>,
+ * that can encode and/or decode HTTP content.</p>
+ * <p>Implementations are discovered with the {@link ServiceLoader}, so that a codec
+ * becomes available to both the server and the client by just adding its jar to the
+ * class-path or module-path; {@code gzip} is always available.</p>
+ * <p>Encoding is stream based: the server writes the content to the
+ * {@link #newEncoder(OutputStream, int) encoder} that writes the encoded bytes to
+ * the given stream.
+ * Decoding is buffer based: the client pushes encoded buffers to the
+ * {@link #newDecoder(ByteBufferPool) decoder} as they arrive from the network.</p>
+ */
+public abstract class ContentCodec
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ContentCodec.class);
+    private static final List<ContentCodec> __codecs = new ArrayList<>();
+
+    static
+    {
+        Map<String, ContentCodec> codecs = new HashMap<>();
+        ContentCodec gzip = new GzipContentCodec();
+        codecs.put(gzip.getEncoding(), gzip);
+        __codecs.add(gzip);
+        TypeUtil.serviceProviderStream(ServiceLoader.load(ContentCodec.class)).forEach(provider ->
+        {
+            try
+            {
+                ContentCodec codec = provider.get();
+                if (codecs.putIfAbsent(codec.getEncoding(), codec) == null)
+                    __codecs.add(codec);
+                else
+                    LOG.warn("multiple ContentCodecs for {}", codec.getEncoding());
+            }
+            catch (Error | RuntimeException e)
+            {
+                LOG.debug("Unable to add ContentCodec", e);
+            }
+        });
+        LOG.debug("ContentCodecs loaded: {}", __codecs);
+    }
+
+    /**
+     * @return the available codecs, {@code gzip} first
+     */
+    public static List<ContentCodec> getContentCodecs()
+    {
+        return List.copyOf(__codecs);
+    }
+
+    /**
+     * @param encoding the content coding name
+     * @return the available codec for the given content coding, or {@code null}
+     */
+    public static ContentCodec getContentCodec(String encoding)
+    {
+        for (ContentCodec codec : __codecs)
+        {
+            if (codec.getEncoding().equalsIgnoreCase(encoding))
+                return codec;
+        }
+        return null;
+    }
+
+    /**
+     * <p>Negotiates the content coding from the {@code Accept-Encoding} request header values.</p>
+     * <p>The codec with the highest quality value is selected; when several codecs have
+     * the same quality value, the one that comes first in the given codecs is selected.
+     * A wildcard {@code *} matches any codec not explicitly listed, and codecs with a
+     * quality value of {@code 0} are never selected.</p>
+     *
+     * @param acceptEncodings the {@code Accept-Encoding} header values
+     * @param codecs the candidate codecs, in order of server preference
+     * @return the negotiated codec, or {@code null} if none of the given codecs is acceptable
+     */
+    public static ContentCodec negotiate(List<String> acceptEncodings, Collection<ContentCodec> codecs)
+    {
+        if (acceptEncodings == null || acceptEncodings.isEmpty())
+            return null;
+
+        Map<String, Double> qualities = new HashMap<>();
+        Map<String, String> parameters = new HashMap<>();
+        for (String value : new QuotedCSV(false, acceptEncodings.toArray(new String[0])))
+        {
+            parameters.clear();
+            String coding = HttpField.valueParameters(value, parameters).trim().toLowerCase(Locale.ENGLISH);
+            qualities.put(coding, quality(parameters.get("q")));
+        }
+
+        Double wildcard = qualities.get("*");
+        ContentCodec result = null;
+        double best = 0.0D;
+        for (ContentCodec codec : codecs)
+        {
+            Double quality = qualities.get(codec.getEncoding().toLowerCase(Locale.ENGLISH));
+            if (quality == null)
+                quality = wildcard;
+            if (quality != null && quality > best)
+            {
+                result = codec;
+                best = quality;
+            }
+        }
+        return result;
+    }
+
+    private static double quality(String value)
+    {
+        if (value == null)
+            return 1.0D;
+        try
+        {
+            double quality = Double.parseDouble(value);
+            return quality < 0 ? 0.0D : Math.min(quality, 1.0D);
+        }
+        catch (NumberFormatException x)
+        {
+            return 0.0D;
+        }
+    }
+
+    private final String encoding;
+
+    protected ContentCodec(String encoding)
+    {
+        this.encoding = encoding;
+    }
+
+    /**
+     * @return the content coding name, such as {@code gzip}
+     */
+    public String getEncoding()
+    {
+        return encoding;
+    }
+
+    /**
+     * @return whether this codec supports encoding
+     */
+    public boolean isEncodingSupported()
+    {
+        return true;
+    }
+
+    /**
+     * @return whether this codec supports decoding
+     */
+    public boolean isDecodingSupported()
+    {
+        return true;
+    }
+
+    /**
+     * @return the default compression level of this codec
+     */
+    public abstract int getDefaultLevel();
+
+    /**
+     * <p>Creates a new encoder that writes the encoded content to the given stream.</p>
+     * <p>Closing the encoder writes the encoding trailer, if any, and closes the given stream.</p>
+     *
+     * @param output the stream the encoded content is written to
+     * @param level the compression level, or a negative value for the default level
+     * @return a new encoder
+     * @throws IOException if the encoder cannot be created
+     * @throws UnsupportedOperationException if this codec does not support encoding
+     */
+    public abstract OutputStream newEncoder(OutputStream output, int level) throws IOException;
+
+    /**
+     * @param byteBufferPool the pool to acquire the decoded buffers from, or {@code null}
+     * @return a new decoder
+     * @throws UnsupportedOperationException if this codec does not support decoding
+     */
+    public abstract Decoder newDecoder(ByteBufferPool byteBufferPool);
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), getEncoding());
+    }
+
+    /**
+     * <p>A push-based decoder of encoded content.</p>
+     */
+    public interface Decoder extends Destroyable
+    {
+        /**
+         * <p>Decodes the bytes in the given buffer.</p>
+         * <p>This method may be called repeatedly with the same buffer
+         * until the buffer has no more bytes remaining.</p>
+         *
+         * @param buffer the buffer containing encoded bytes
+         * @return a buffer containing decoded bytes, possibly empty if more encoded bytes are needed
+         */
+        ByteBuffer decode(ByteBuffer buffer);
+
+        /**
+         * @param decoded a buffer returned by {@link #decode(ByteBuffer)}, to be released
+         */
+        default void release(ByteBuffer decoded)
+        {
+        }
+
+        @Override
+        default void destroy()
+        {
+        }
+    }
+}
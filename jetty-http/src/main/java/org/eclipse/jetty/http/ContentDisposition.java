@@ -0,0 +1,584 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.CharacterCodingException;
+import java.nio.charset.Charset;
+import java.nio.charset.CodingErrorAction;
+import java.nio.charset.StandardCharsets;
+import java.util.Collections;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.TypeUtil;
+
+/**
+ * <p>A {@code Content-Disposition} header value, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc6266">RFC 6266</a>
+ * and, for {@code multipart/form-data} parts, by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc7578#section-4.2">RFC 7578</a>.</p>
+ * <p>Generation handles the encoding of file names: ASCII names are sent as a
+ * quoted {@code filename} parameter, while non-ASCII names are sent both as
+ * an ASCII fallback {@code filename} parameter and as a {@code filename*}
+ * parameter encoded as per <a href="https://datatracker.ietf.org/doc/html/rfc8187">RFC 8187</a>.
+ * For {@code form-data} the {@code filename*} parameter must not be used,
+ * so names are sent in UTF-8 with {@code "}, CR and LF percent-encoded,
+ * as browsers do.</p>
+ * <p>Parsing is lenient, to cope with what user agents actually send:
+ * within quoted strings only {@code \"} and {@code \\} are escape sequences,
+ * so that unescaped Windows paths are preserved, and a {@code name*}
+ * parameter, when it can be decoded, takes precedence over {@code name}.</p>
+ * <pre>{@code
+ * // Content-Disposition: attachment; filename="_ rates.txt"; filename*=UTF-8''%E2%82%AC%20rates.txt
+ * response.setHeader("Content-Disposition", ContentDisposition.attachment("€ rates.txt").asString());
+ *
+ * ContentDisposition disposition = ContentDisposition.parse(request.getHeader("Content-Disposition"));
+ * String fileName = disposition.getFileName();
+ * }</pre>
+ */
+public class ContentDisposition
+{
+    public static final String ATTACHMENT = "attachment";
+    public static final String INLINE = "inline";
+    public static final String FORM_DATA = "form-data";
+    public static final String NAME = "name";
+    public static final String FILENAME = "filename";
+
+    private final String _type;
+    private final Map<String, String> _parameters = new LinkedHashMap<>();
+
+    /**
+     * @param type the disposition type, for example {@link #ATTACHMENT}
+     * @throws IllegalArgumentException if the type is not a valid token
+     */
+    public ContentDisposition(String type)
+    {
+        if (!isToken(type))
+            throw new IllegalArgumentException("Invalid disposition type: " + type);
+        _type = StringUtil.asciiToLowerCase(type);
+    }
+
+    /**
+     * @param fileName the file name, or null
+     * @return an {@code attachment} disposition with the given file name
+     */
+    public static ContentDisposition attachment(String fileName)
+    {
+        return new ContentDisposition(ATTACHMENT).parameter(FILENAME, fileName);
+    }
+
+    /**
+     * @param fileName the file name, or null
+     * @return an {@code inline} disposition with the given file name
+     */
+    public static ContentDisposition inline(String fileName)
+    {
+        return new ContentDisposition(INLINE).parameter(FILENAME, fileName);
+    }
+
+    /**
+     * @param name the name of the form field
+     * @param fileName the file name, or null if the part is not a file
+     * @return a {@code form-data} disposition with the given name and file name
+     */
+    public static ContentDisposition formData(String name, String fileName)
+    {
+        return new ContentDisposition(FORM_DATA)
+            .parameter(NAME, Objects.requireNonNull(name))
+            .parameter(FILENAME, fileName);
+    }
+
+    /**
+     * <p>Parses a {@code Content-Disposition} header value.</p>
+     *
+     * @param value the header value
+     * @return the parsed disposition
+     * @throws IllegalArgumentException if the value cannot be parsed
+     */
+    public static ContentDisposition parse(String value)
+    {
+        if (value == null)
+            throw new IllegalArgumentException("Missing Content-Disposition");
+
+        int length = value.length();
+        int i = skipWhiteSpace(value, 0);
+        int start = i;
+        while (i < length && value.charAt(i) != ';' && !isWhiteSpace(value.charAt(i)))
+        {
+            ++i;
+        }
+        ContentDisposition result = new ContentDisposition(value.substring(start, i));
+
+        Set<String> extended = new HashSet<>();
+        while (true)
+        {
+            i = skipWhiteSpace(value, i);
+            if (i == length)
+                break;
+            if (value.charAt(i) != ';')
+                throw new IllegalArgumentException("Invalid Content-Disposition: " + value);
+            i = skipWhiteSpace(value, i + 1);
+            if (i == length)
+                break;
+
+            start = i;
+            while (i < length && value.charAt(i) != '=' && value.charAt(i) != ';')
+            {
+                ++i;
+            }
+            String name = StringUtil.asciiToLowerCase(value.substring(start, i).trim());
+            if (i == length || value.charAt(i) == ';')
+            {
+                // Ignore parameters without a value.
+                continue;
+            }
+            i = skipWhiteSpace(value, i + 1);
+
+            StringBuilder builder = new StringBuilder();
+            if (i < length && value.charAt(i) == '"')
+            {
+                i = parseQuotedString(value, i + 1, builder);
+            }
+            else
+            {
+                start = i;
+                while (i < length && value.charAt(i) != ';')
+                {
+                    ++i;
+                }
+                builder.append(value, start, i);
+                // Remove trailing whitespace.
+                while (builder.length() > 0 && isWhiteSpace(builder.charAt(builder.length() - 1)))
+                {
+                    builder.setLength(builder.length() - 1);
+                }
+            }
+
+            if (name.endsWith("*"))
+            {
+                name = name.substring(0, name.length() - 1);
+                // An ext-value that cannot be decoded is ignored,
+                // so that the plain parameter may be used instead.
+                String decoded = decodeExtValue(builder.toString());
+                if (decoded != null)
+                {
+                    extended.add(name);
+                    result._parameters.put(name, decoded);
+                }
+            }
+            else if (!extended.contains(name))
+            {
+                result._parameters.putIfAbsent(name, builder.toString());
+            }
+        }
+        return result;
+    }
+
+    /**
+     * @param value the header value
+     * @param defaultValue the value to return if the header value cannot be parsed
+     * @return the parsed disposition, or the default value
+     */
+    public static ContentDisposition parse(String value, ContentDisposition defaultValue)
+    {
+        try
+        {
+            return parse(value);
+        }
+        catch (IllegalArgumentException x)
+        {
+            return defaultValue;
+        }
+    }
+
+    /**
+     * @return the lower-case disposition type
+     */
+    public String getType()
+    {
+        return _type;
+    }
+
+    public boolean isAttachment()
+    {
+        return ATTACHMENT.equals(_type);
+    }
+
+    public boolean isInline()
+    {
+        return INLINE.equals(_type);
+    }
+
+    public boolean isFormData()
+    {
+        return FORM_DATA.equals(_type);
+    }
+
+    /**
+     * @return the {@code name} parameter, or null
+     */
+    public String getName()
+    {
+        return getParameter(NAME);
+    }
+
+    /**
+     * <p>Returns the decoded file name, taken from the {@code filename*}
+     * parameter if present and from the {@code filename} parameter otherwise.</p>
+     * <p>The file name is returned as sent by the other peer, and may contain
+     * path separators: callers that use it to create files must sanitize it.</p>
+     *
+     * @return the file name, or null
+     */
+    public String getFileName()
+    {
+        return getParameter(FILENAME);
+    }
+
+    /**
+     * @param name the case-insensitive parameter name, without the trailing {@code *}
+     * @return the decoded parameter value, or null
+     */
+    public String getParameter(String name)
+    {
+        return _parameters.get(StringUtil.asciiToLowerCase(name));
+    }
+
+    /**
+     * @return the decoded parameters, keyed by lower-case name
+     */
+    public Map<String, String> getParameters()
+    {
+        return Collections.unmodifiableMap(_parameters);
+    }
+
+    /**
+     * @param name the parameter name, without the trailing {@code *}
+     * @param value the parameter value, or null to remove the parameter
+     * @return this instance
+     */
+    public ContentDisposition parameter(String name, String value)
+    {
+        if (!isToken(name) || name.endsWith("*"))
+            throw new IllegalArgumentException("Invalid parameter name: " + name);
+        name = StringUtil.asciiToLowerCase(name);
+        if (value == null)
+            _parameters.remove(name);
+        else
+            _parameters.put(name, value);
+        return this;
+    }
+
+    /**
+     * @return the header value
+     */
+    public String asString()
+    {
+        StringBuilder builder = new StringBuilder(_type);
+        for (Map.Entry<String, String> entry : _parameters.entrySet())
+        {
+            String name = entry.getKey();
+            String value = entry.getValue();
+            builder.append("; ").append(name).append('=');
+            if (isFormData())
+            {
+                appendFormData(builder, value);
+            }
+            else if (isPrintableAscii(value))
+            {
+                appendQuoted(builder, value);
+            }
+            else
+            {
+                // RFC 6266 section 4.3: send an ASCII fallback before the
+                // encoded value, for user agents that do not support it.
+                if (FILENAME.equals(name))
+                {
+                    appendQuoted(builder, toAsciiFallback(value));
+                    builder.append("; ").append(name).append('=');
+                }
+                builder.setLength(builder.length() - 1);
+                builder.append("*=");
+                appendExtValue(builder, value);
+            }
+        }
+        return builder.toString();
+    }
+
+    /**
+     * @return a {@code Content-Disposition} field with this value
+     */
+    public HttpField asHttpField()
+    {
+        return new HttpField(HttpHeader.CONTENT_DISPOSITION, asString());
+    }
+
+    @Override
+    public boolean equals(Object obj)
+    {
+        if (this == obj)
+            return true;
+        if (!(obj instanceof ContentDisposition))
+            return false;
+        ContentDisposition that = (ContentDisposition)obj;
+        return _type.equals(that._type) && _parameters.equals(that._parameters);
+    }
+
+    @Override
+    public int hashCode()
+    {
+        return Objects.hash(_type, _parameters);
+    }
+
+    @Override
+    public String toString()
+    {
+        return asString();
+    }
+
+    private static int parseQuotedString(String value, int i, StringBuilder builder)
+    {
+        int length = value.length();
+        while (i < length)
+        {
+            char c = value.charAt(i++);
+            if (c == '"')
+                return i;
+            if (c == '\\' && i < length)
+            {
+                char next = value.charAt(i);
+                // Only \" and \\ are escapes; user agents do not escape
+                // other backslashes, for example in Windows paths.
+                // A \" that ends the value is taken as a trailing
+                // backslash in an unescaped path such as "c:\dir\".
+                if (next == '\\' || next == '"' && !isEndOfValue(value, i + 1))
+                {
+                    builder.append(next);
+                    ++i;
+                    continue;
+                }
+            }
+            builder.append(c);
+        }
+        throw new IllegalArgumentException("Unterminated quoted string: " + value);
+    }
+
+    private static boolean isEndOfValue(String value, int i)
+    {
+        i = skipWhiteSpace(value, i);
+        return i == value.length() || value.charAt(i) == ';';
+    }
+
+    private static String decodeExtValue(String value)
+    {
+        // ext-value = charset "'" [ language ] "'" value-chars
+        int charsetEnd = value.indexOf('\'');
+        if (charsetEnd < 0)
+            return null;
+        int languageEnd = value.indexOf('\'', charsetEnd + 1);
+        if (languageEnd < 0)
+            return null;
+
+        Charset charset;
+        try
+        {
+            charset = Charset.forName(value.substring(0, charsetEnd));
+        }
+        catch (IllegalArgumentException x)
+        {
+            return null;
+        }
+
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream(value.length());
+        for (int i = languageEnd + 1; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            if (c == '%')
+            {
+                if (i + 2 >= value.length())
+                    return null;
+                HttpTokens.Token hi = HttpTokens.getToken(value.charAt(i + 1));
+                HttpTokens.Token lo = HttpTokens.getToken(value.charAt(i + 2));
+                if (hi == null || !hi.isHexDigit() || lo == null || !lo.isHexDigit())
+                    return null;
+                bytes.write(hi.getHexDigit() * 16 + lo.getHexDigit());
+                i += 2;
+            }
+            else if (c > 0x20 && c < 0x7F)
+            {
+                bytes.write(c);
+            }
+            else
+            {
+                return null;
+            }
+        }
+
+        try
+        {
+            return charset.newDecoder()
+                .onMalformedInput(CodingErrorAction.REPORT)
+                .onUnmappableCharacter(CodingErrorAction.REPORT)
+                .decode(ByteBuffer.wrap(bytes.toByteArray()))
+                .toString();
+        }
+        catch (CharacterCodingException x)
+        {
+            return null;
+        }
+    }
+
+    private static void appendExtValue(StringBuilder builder, String value)
+    {
+        builder.append("UTF-8''");
+        for (byte b : value.getBytes(StandardCharsets.UTF_8))
+        {
+            if (isAttrChar(b))
+            {
+                builder.append((char)b);
+            }
+            else
+            {
+                builder.append('%');
+                TypeUtil.toHex(b, builder);
+            }
+        }
+    }
+
+    private static void appendQuoted(StringBuilder builder, String value)
+    {
+        builder.append('"');
+        for (int i = 0; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            if (c == '"' || c == '\\')
+                builder.append('\\');
+            builder.append(c);
+        }
+        builder.append('"');
+    }
+
+    private static void appendFormData(StringBuilder builder, String value)
+    {
+        // Encode like the HTML specification does for multipart/form-data.
+        builder.append('"');
+        for (int i = 0; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            switch (c)
+            {
+                case '"':
+                    builder.append("%22");
+                    break;
+                case '\r':
+                    builder.append("%0D");
+                    break;
+                case '\n':
+                    builder.append("%0A");
+                    break;
+                default:
+                    builder.append(c);
+                    break;
+            }
+        }
+        builder.append('"');
+    }
+
+    private static String toAsciiFallback(String value)
+    {
+        StringBuilder builder = new StringBuilder(value.length());
+        for (int i = 0; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            // Surrogate pairs are replaced by a single character.
+            if (Character.isHighSurrogate(c) && i + 1 < value.length() && Character.isLowSurrogate(value.charAt(i + 1)))
+                ++i;
+            builder.append(isPrintableAscii(c) ? c : '_');
+        }
+        return builder.toString();
+    }
+
+    private static boolean isPrintableAscii(String value)
+    {
+        for (int i = 0; i < value.length(); ++i)
+        {
+            if (!isPrintableAscii(value.charAt(i)))
+                return false;
+        }
+        return true;
+    }
+
+    private static boolean isPrintableAscii(char c)
+    {
+        return c >= 0x20 && c < 0x7F;
+    }
+
+    private static boolean isAttrChar(byte b)
+    {
+        // attr-char = ALPHA / DIGIT / "!" / "#" / "$" / "&" / "+" / "-" / "." / "^" / "_" / "`" / "|" / "~"
+        if (b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9')
+            return true;
+        switch (b)
+        {
+            case '!':
+            case '#':
+            case '$':
+            case '&':
+            case '+':
+            case '-':
+            case '.':
+            case '^':
+            case '_':
+            case '`':
+            case '|':
+            case '~':
+                return true;
+            default:
+                return false;
+        }
+    }
+
+    private static boolean isToken(String value)
+    {
+        if (value == null || value.isEmpty())
+            return false;
+        for (int i = 0; i < value.length(); ++i)
+        {
+            HttpTokens.Token token = HttpTokens.getToken(value.charAt(i));
+            if (token == null || !token.isRfc2616Token())
+                return false;
+        }
+        return true;
+    }
+
+    private static boolean isWhiteSpace(char c)
+    {
+        return c == ' ' || c == '\t';
+    }
+
+    private static int skipWhiteSpace(String value, int i)
+    {
+        while (i < value.length() && isWhiteSpace(value.charAt(i)))
+        {
+            ++i;
+        }
+        return i;
+    }
+}
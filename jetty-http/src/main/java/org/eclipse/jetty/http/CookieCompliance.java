@@ -99,7 +99,13 @@ public class CookieCompliance implements ComplianceViolation.Mode
         /**
          * Allow spaces within values without quotes.
          */
-        SPACE_IN_VALUES("https://www.rfc-editor.org/rfc/rfc6265#section-5.2", "Space in value");
+        SPACE_IN_VALUES("https://www.rfc-editor.org/rfc/rfc6265#section-5.2", "Space in value"),
+
+        /**
+         * A response cookie does not meet the requirements of its {@code __Secure-} or {@code __Host-} name prefix.
+         * When this violation is not allowed, adding such a cookie to a response throws an exception.
+         */
+        INVALID_PREFIX("https://datatracker.ietf.org/doc/html/draft-ietf-httpbis-rfc6265bis#section-4.1.3", "Cookie prefix requirements not met");
 
         private final String url;
         private final String description;
@@ -134,11 +140,26 @@ public class CookieCompliance implements ComplianceViolation.Mode
      * but allows:</p>
      * <ul>
      * <li>{@link Violation#INVALID_COOKIES}</li>
+     * <li>{@link Violation#INVALID_PREFIX}</li>
      * <li>{@link Violation#OPTIONAL_WHITE_SPACE}</li>
      * <li>{@link Violation#SPACE_IN_VALUES}</li>
      * </ul>
      */
     public static final CookieCompliance RFC6265 = new CookieCompliance("RFC6265", of(
+        Violation.INVALID_COOKIES, Violation.INVALID_PREFIX, Violation.OPTIONAL_WHITE_SPACE, Violation.SPACE_IN_VALUES)
+    );
+
+    /**
+     * <p>A CookieCompliance mode that enforces <a href="https://datatracker.ietf.org/doc/html/draft-ietf-httpbis-rfc6265bis">RFC 6265bis</a>
+     * compliance, in particular the requirements of the {@code __Secure-} and {@code __Host-} cookie name prefixes,
+     * but allows:</p>
+     * <ul>
+     * <li>{@link Violation#INVALID_COOKIES}</li>
+     * <li>{@link Violation#OPTIONAL_WHITE_SPACE}</li>
+     * <li>{@link Violation#SPACE_IN_VALUES}</li>
+     * </ul>
+     */
+    public static final CookieCompliance RFC6265BIS = new CookieCompliance("RFC6265BIS", of(
         Violation.INVALID_COOKIES, Violation.OPTIONAL_WHITE_SPACE, Violation.SPACE_IN_VALUES)
     );
 
@@ -155,13 +176,14 @@ public class CookieCompliance implements ComplianceViolation.Mode
      * <li>{@link Violation#BAD_QUOTES}</li>
      * <li>{@link Violation#ESCAPE_IN_QUOTES}</li>
      * <li>{@link Violation#INVALID_COOKIES}</li>
+     * <li>{@link Violation#INVALID_PREFIX}</li>
      * <li>{@link Violation#OPTIONAL_WHITE_SPACE}</li>
      * <li>{@link Violation#SPECIAL_CHARS_IN_QUOTES}</li>
      * <li>{@link Violation#SPACE_IN_VALUES}</li>
      * </ul>
      */
     public static final CookieCompliance RFC6265_LEGACY = new CookieCompliance("RFC6265_LEGACY", EnumSet.of(
-        Violation.ATTRIBUTES, Violation.BAD_QUOTES, Violation.ESCAPE_IN_QUOTES, Violation.INVALID_COOKIES, Violation.OPTIONAL_WHITE_SPACE, Violation.SPECIAL_CHARS_IN_QUOTES, Violation.SPACE_IN_VALUES)
+        Violation.ATTRIBUTES, Violation.BAD_QUOTES, Violation.ESCAPE_IN_QUOTES, Violation.INVALID_COOKIES, Violation.INVALID_PREFIX, Violation.OPTIONAL_WHITE_SPACE, Violation.SPECIAL_CHARS_IN_QUOTES, Violation.SPACE_IN_VALUES)
     );
 
     /**
@@ -182,7 +204,7 @@ public class CookieCompliance implements ComplianceViolation.Mode
         Violation.BAD_QUOTES, Violation.COMMA_NOT_VALID_OCTET, Violation.RESERVED_NAMES_NOT_DOLLAR_PREFIXED)
     ));
 
-    private static final List<CookieCompliance> KNOWN_MODES = Arrays.asList(RFC6265, RFC6265_STRICT, RFC6265_LEGACY, RFC2965, RFC2965_LEGACY);
+    private static final List<CookieCompliance> KNOWN_MODES = Arrays.asList(RFC6265, RFC6265_STRICT, RFC6265BIS, RFC6265_LEGACY, RFC2965, RFC2965_LEGACY);
     private static final AtomicInteger __custom = new AtomicInteger();
 
     public static CookieCompliance valueOf(String name)
@@ -0,0 +1,78 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.util.zip.Deflater;
+import java.util.zip.GZIPOutputStream;
+
+import org.eclipse.jetty.io.ByteBufferPool;
+
+/**
+ * <p>The {@code gzip} {@link ContentCodec}, always available.</p>
+ */
+public class GzipContentCodec extends ContentCodec
+{
+    private static final int BUFFER_SIZE = 8192;
+
+    public GzipContentCodec()
+    {
+        super("gzip");
+    }
+
+    @Override
+    public int getDefaultLevel()
+    {
+        return Deflater.DEFAULT_COMPRESSION;
+    }
+
+    @Override
+    public OutputStream newEncoder(OutputStream output, int level) throws IOException
+    {
+        return new GZIPOutputStream(output, BUFFER_SIZE)
+        {
+            {
+                def.setLevel(level < 0 ? Deflater.DEFAULT_COMPRESSION : level);
+            }
+        };
+    }
+
+    @Override
+    public Decoder newDecoder(ByteBufferPool byteBufferPool)
+    {
+        GZIPContentDecoder decoder = new GZIPContentDecoder(byteBufferPool, BUFFER_SIZE);
+        return new Decoder()
+        {
+            @Override
+            public ByteBuffer decode(ByteBuffer buffer)
+            {
+                return decoder.decode(buffer);
+            }
+
+            @Override
+            public void release(ByteBuffer decoded)
+            {
+                decoder.release(decoded);
+            }
+
+            @Override
+            public void destroy()
+            {
+                decoder.destroy();
+            }
+        };
+    }
+}
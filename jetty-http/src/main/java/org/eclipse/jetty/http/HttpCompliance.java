@@ -124,7 +124,15 @@ public final class HttpCompliance implements ComplianceViolation.Mode
          * must reject a request if the target URI has an authority that is different than a provided Host header.
          * A deployment may include this violation to allow different values on the target URI and the Host header on a received request.
          */
-        MISMATCHED_AUTHORITY("https://www.rfc-editor.org/rfc/rfc7230#section-5.4", "Mismatched Authority");
+        MISMATCHED_AUTHORITY("https://www.rfc-editor.org/rfc/rfc7230#section-5.4", "Mismatched Authority"),
+
+        /**
+         * Since <a href="https://www.rfc-editor.org/rfc/rfc7230#section-4.1.1">RFC 7230</a>, the chunk size of a chunked
+         * body may only be followed by well formed chunk extensions, in the form {@code ;name=value}.  Jetty ignores chunk
+         * extensions, and historically it has not validated them; however, front-end proxies that parse chunk extensions
+         * differently may be abused to smuggle requests, so only the {@link #PARANOID} mode rejects invalid chunk extensions.
+         */
+        INVALID_CHUNK_EXTENSION("https://www.rfc-editor.org/rfc/rfc7230#section-4.1.1", "Invalid chunk extension");
 
         private final String url;
         private final String description;
@@ -161,9 +169,19 @@ public final class HttpCompliance implements ComplianceViolation.Mode
 
     /**
      * The HttpCompliance mode that supports <a href="https://tools.ietf.org/html/rfc7230">RFC 7230</a>
-     * with no known violations.
+     * with no known violations, except for the lenient parsing of chunk extensions that Jetty has always had.
+     */
+    public static final HttpCompliance RFC7230 = new HttpCompliance("RFC7230", of(Violation.INVALID_CHUNK_EXTENSION));
+
+    /**
+     * <p>The strictest HttpCompliance mode, that allows no violations at all.</p>
+     * <p>This mode is intended to defend against request smuggling, where a front-end proxy and Jetty disagree
+     * on the framing of a request: it rejects requests with both {@code Transfer-Encoding} and {@code Content-Length},
+     * multiple {@code Content-Length} values, folded (obs-fold) field values and invalid chunk extensions.</p>
+     * <p>Rejected requests are reported to {@link ComplianceViolation.Listener}s as
+     * {@link ComplianceViolation.Event#isAllowed() not allowed} events.</p>
      */
-    public static final HttpCompliance RFC7230 = new HttpCompliance("RFC7230", noneOf(Violation.class));
+    public static final HttpCompliance PARANOID = new HttpCompliance("PARANOID", noneOf(Violation.class));
 
     /**
      * The HttpCompliance mode that supports <a href="https://tools.ietf.org/html/rfc2616">RFC 7230</a>
@@ -172,7 +190,8 @@ public final class HttpCompliance implements ComplianceViolation.Mode
     public static final HttpCompliance RFC2616 = new HttpCompliance("RFC2616", of(
         Violation.HTTP_0_9,
         Violation.MULTILINE_FIELD_VALUE,
-        Violation.MISMATCHED_AUTHORITY
+        Violation.MISMATCHED_AUTHORITY,
+        Violation.INVALID_CHUNK_EXTENSION
     ));
 
     /**
@@ -195,7 +214,7 @@ public final class HttpCompliance implements ComplianceViolation.Mode
      */
     public static final HttpCompliance RFC7230_LEGACY = RFC7230.with("RFC7230_LEGACY", Violation.CASE_INSENSITIVE_METHOD);
 
-    private static final List<HttpCompliance> KNOWN_MODES = Arrays.asList(RFC7230, RFC2616, LEGACY, RFC2616_LEGACY, RFC7230_LEGACY);
+    private static final List<HttpCompliance> KNOWN_MODES = Arrays.asList(RFC7230, RFC2616, LEGACY, RFC2616_LEGACY, RFC7230_LEGACY, PARANOID);
     private static final AtomicInteger __custom = new AtomicInteger();
 
     /**
@@ -43,11 +43,32 @@ public class HttpCookie
     public static final String SAME_SITE_NONE_COMMENT = SAME_SITE_COMMENT + "NONE__";
     public static final String SAME_SITE_LAX_COMMENT = SAME_SITE_COMMENT + "LAX__";
     public static final String SAME_SITE_STRICT_COMMENT = SAME_SITE_COMMENT + "STRICT__";
+    /**
+     * If this string is found within the comment parsed with {@link #isPartitionedInComment(String)} the check will return true
+     **/
+    public static final String PARTITIONED_COMMENT = "__PARTITIONED__";
 
     /**
      * Name of context attribute with default SameSite cookie value
      */
     public static final String SAME_SITE_DEFAULT_ATTRIBUTE = "org.eclipse.jetty.cookie.sameSiteDefault";
+    /**
+     * Name of context attribute with the default Secure cookie value, applied to responses to secure requests
+     */
+    public static final String SECURE_DEFAULT_ATTRIBUTE = "org.eclipse.jetty.cookie.secureDefault";
+    /**
+     * Name of context attribute with the default Partitioned cookie value, applied to Secure cookies with SameSite=None
+     */
+    public static final String PARTITIONED_DEFAULT_ATTRIBUTE = "org.eclipse.jetty.cookie.partitionedDefault";
+
+    /**
+     * The cookie name prefix that requires the Secure attribute
+     */
+    public static final String SECURE_PREFIX = "__Secure-";
+    /**
+     * The cookie name prefix that requires the Secure attribute, the {@code /} path and no domain
+     */
+    public static final String HOST_PREFIX = "__Host-";
 
     public enum SameSite
     {
@@ -77,6 +98,7 @@ public class HttpCookie
     private final boolean _httpOnly;
     private final long _expiration;
     private final SameSite _sameSite;
+    private final boolean _partitioned;
 
     public HttpCookie(String name, String value)
     {
@@ -104,6 +126,11 @@ public class HttpCookie
     }
 
     public HttpCookie(String name, String value, String domain, String path, long maxAge, boolean httpOnly, boolean secure, String comment, int version, SameSite sameSite)
+    {
+        this(name, value, domain, path, maxAge, httpOnly, secure, comment, version, sameSite, false);
+    }
+
+    public HttpCookie(String name, String value, String domain, String path, long maxAge, boolean httpOnly, boolean secure, String comment, int version, SameSite sameSite, boolean partitioned)
     {
         _name = name;
         _value = value;
@@ -116,6 +143,7 @@ public class HttpCookie
         _version = version;
         _expiration = maxAge < 0 ? -1 : NanoTime.now() + TimeUnit.SECONDS.toNanos(maxAge);
         _sameSite = sameSite;
+        _partitioned = partitioned;
     }
 
     public HttpCookie(String setCookie)
@@ -136,8 +164,51 @@ public class HttpCookie
         _comment = cookie.getComment();
         _version = cookie.getVersion();
         _expiration = _maxAge < 0 ? -1 : NanoTime.now() + TimeUnit.SECONDS.toNanos(_maxAge);
-        // support for SameSite values has not yet been added to java.net.HttpCookie
-        _sameSite = getSameSiteFromComment(cookie.getComment());
+        // support for SameSite and Partitioned has not yet been added to java.net.HttpCookie
+        SameSite sameSite = null;
+        boolean partitioned = false;
+        String[] attributes = setCookie.split(";");
+        // The first element is the name and value of the cookie.
+        for (int i = 1; i < attributes.length; i++)
+        {
+            String attribute = attributes[i];
+            int equals = attribute.indexOf('=');
+            String name = (equals < 0 ? attribute : attribute.substring(0, equals)).trim();
+            if ("Partitioned".equalsIgnoreCase(name))
+                partitioned = true;
+            else if ("SameSite".equalsIgnoreCase(name) && equals > 0)
+                sameSite = getSameSite(attribute.substring(equals + 1).trim());
+        }
+        _sameSite = sameSite == null ? getSameSiteFromComment(cookie.getComment()) : sameSite;
+        _partitioned = partitioned || isPartitionedInComment(cookie.getComment());
+    }
+
+    /**
+     * @param name the cookie name
+     * @param value the cookie value
+     * @return a builder for a cookie with the given name and value
+     */
+    public static Builder build(String name, String value)
+    {
+        return new Builder(name, value);
+    }
+
+    /**
+     * @param cookie the cookie to copy
+     * @return a builder initialized with the attributes of the given cookie
+     */
+    public static Builder build(HttpCookie cookie)
+    {
+        return new Builder(cookie.getName(), cookie.getValue())
+            .domain(cookie.getDomain())
+            .path(cookie.getPath())
+            .maxAge(cookie.getMaxAge())
+            .httpOnly(cookie.isHttpOnly())
+            .secure(cookie.isSecure())
+            .comment(cookie.getComment())
+            .version(cookie.getVersion())
+            .sameSite(cookie.getSameSite())
+            .partitioned(cookie.isPartitioned());
     }
 
     /**
@@ -212,6 +283,14 @@ public class HttpCookie
         return _sameSite;
     }
 
+    /**
+     * @return whether the cookie is partitioned by top level site, as defined by CHIPS
+     */
+    public boolean isPartitioned()
+    {
+        return _partitioned;
+    }
+
     /**
      * @return whether the cookie is valid for the http protocol only
      */
@@ -419,15 +498,46 @@ public class HttpCookie
             buf.append("; SameSite=");
             buf.append(_sameSite.getAttributeValue());
         }
+        if (_partitioned)
+            buf.append("; Partitioned");
 
         return buf.toString();
     }
 
+    /**
+     * <p>Checks the requirements of the cookie name prefixes defined by RFC 6265bis:
+     * a {@code __Secure-} cookie must be Secure, while a {@code __Host-} cookie must
+     * also have the {@code /} path and no domain.</p>
+     *
+     * @param cookie the cookie to check
+     * @throws IllegalArgumentException if the cookie does not meet the requirements of its name prefix
+     */
+    public static void requireValidPrefix(HttpCookie cookie)
+    {
+        String name = cookie.getName();
+        if (name == null)
+            return;
+        boolean host = name.regionMatches(true, 0, HOST_PREFIX, 0, HOST_PREFIX.length());
+        if (!host && !name.regionMatches(true, 0, SECURE_PREFIX, 0, SECURE_PREFIX.length()))
+            return;
+        if (!cookie.isSecure())
+            throw new IllegalArgumentException("Cookie " + name + " must be Secure");
+        if (host && StringUtil.isNotBlank(cookie.getDomain()))
+            throw new IllegalArgumentException("Cookie " + name + " must not have a Domain");
+        if (host && !"/".equals(cookie.getPath()))
+            throw new IllegalArgumentException("Cookie " + name + " must have Path=/");
+    }
+
     public static boolean isHttpOnlyInComment(String comment)
     {
         return comment != null && comment.contains(HTTP_ONLY_COMMENT);
     }
 
+    public static boolean isPartitionedInComment(String comment)
+    {
+        return comment != null && comment.contains(PARTITIONED_COMMENT);
+    }
+
     public static SameSite getSameSiteFromComment(String comment)
     {
         if (comment != null)
@@ -485,6 +595,50 @@ public class HttpCookie
         }
     }
 
+    /**
+     * Get the default value for the Secure cookie attribute, if one
+     * has been set for the given context.
+     *
+     * @param contextAttributes the context to check for default Secure value
+     * @return the default Secure value or null if one does not exist
+     */
+    public static Boolean getSecureDefault(Attributes contextAttributes)
+    {
+        return getBooleanDefault(contextAttributes, SECURE_DEFAULT_ATTRIBUTE);
+    }
+
+    /**
+     * Get the default value for the Partitioned cookie attribute, if one
+     * has been set for the given context.
+     *
+     * @param contextAttributes the context to check for default Partitioned value
+     * @return the default Partitioned value or null if one does not exist
+     */
+    public static Boolean getPartitionedDefault(Attributes contextAttributes)
+    {
+        return getBooleanDefault(contextAttributes, PARTITIONED_DEFAULT_ATTRIBUTE);
+    }
+
+    private static Boolean getBooleanDefault(Attributes contextAttributes, String name)
+    {
+        if (contextAttributes == null)
+            return null;
+        Object o = contextAttributes.getAttribute(name);
+        if (o == null || o instanceof Boolean)
+            return (Boolean)o;
+        return Boolean.valueOf(o.toString().trim());
+    }
+
+    private static SameSite getSameSite(String value)
+    {
+        for (SameSite sameSite : SameSite.values())
+        {
+            if (sameSite.getAttributeValue().equalsIgnoreCase(value))
+                return sameSite;
+        }
+        return null;
+    }
+
     public static String getCommentWithoutAttributes(String comment)
     {
         if (comment == null)
@@ -498,13 +652,19 @@ public class HttpCookie
         strippedComment = StringUtil.strip(strippedComment, SAME_SITE_NONE_COMMENT);
         strippedComment = StringUtil.strip(strippedComment, SAME_SITE_LAX_COMMENT);
         strippedComment = StringUtil.strip(strippedComment, SAME_SITE_STRICT_COMMENT);
+        strippedComment = StringUtil.strip(strippedComment, PARTITIONED_COMMENT);
 
         return strippedComment.length() == 0 ? null : strippedComment;
     }
 
     public static String getCommentWithAttributes(String comment, boolean httpOnly, SameSite sameSite)
     {
-        if (comment == null && sameSite == null)
+        return getCommentWithAttributes(comment, httpOnly, sameSite, false);
+    }
+
+    public static String getCommentWithAttributes(String comment, boolean httpOnly, SameSite sameSite, boolean partitioned)
+    {
+        if (comment == null && sameSite == null && !partitioned)
             return null;
 
         StringBuilder builder = new StringBuilder();
@@ -535,11 +695,97 @@ public class HttpCookie
             }
         }
 
+        if (partitioned)
+            builder.append(PARTITIONED_COMMENT);
+
         if (builder.length() == 0)
             return null;
         return builder.toString();
     }
 
+    /**
+     * <p>A builder of {@link HttpCookie}s, that avoids the long constructors.</p>
+     */
+    public static class Builder
+    {
+        private final String _name;
+        private final String _value;
+        private String _domain;
+        private String _path;
+        private long _maxAge = -1;
+        private boolean _httpOnly;
+        private boolean _secure;
+        private String _comment;
+        private int _version;
+        private SameSite _sameSite;
+        private boolean _partitioned;
+
+        private Builder(String name, String value)
+        {
+            _name = name;
+            _value = value;
+        }
+
+        public Builder domain(String domain)
+        {
+            _domain = domain;
+            return this;
+        }
+
+        public Builder path(String path)
+        {
+            _path = path;
+            return this;
+        }
+
+        public Builder maxAge(long maxAge)
+        {
+            _maxAge = maxAge;
+            return this;
+        }
+
+        public Builder httpOnly(boolean httpOnly)
+        {
+            _httpOnly = httpOnly;
+            return this;
+        }
+
+        public Builder secure(boolean secure)
+        {
+            _secure = secure;
+            return this;
+        }
+
+        public Builder comment(String comment)
+        {
+            _comment = comment;
+            return this;
+        }
+
+        public Builder version(int version)
+        {
+            _version = version;
+            return this;
+        }
+
+        public Builder sameSite(SameSite sameSite)
+        {
+            _sameSite = sameSite;
+            return this;
+        }
+
+        public Builder partitioned(boolean partitioned)
+        {
+            _partitioned = partitioned;
+            return this;
+        }
+
+        public HttpCookie build()
+        {
+            return new HttpCookie(_name, _value, _domain, _path, _maxAge, _httpOnly, _secure, _comment, _version, _sameSite, _partitioned);
+        }
+    }
+
     public static class SetCookieHttpField extends HttpField
     {
         final HttpCookie _cookie;
@@ -400,6 +400,107 @@ public interface HttpFields extends Iterable<HttpField>
         return values == null ? Collections.emptyList() : values.getValues();
     }
 
+    /**
+     * Get the combined values of the fields with the given header
+     * as a {@link StructuredFields} Item.
+     *
+     * @param header the header
+     * @return the parsed Item, or null if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid Item
+     */
+    default StructuredFields.Item getStructuredItem(HttpHeader header)
+    {
+        String value = getStructuredValue(header, (f, h) -> f.getHeader() == h);
+        return value == null ? null : StructuredFields.parseItem(value);
+    }
+
+    /**
+     * Get the combined values of the fields with the given name
+     * as a {@link StructuredFields} Item.
+     *
+     * @param name the case-insensitive field name
+     * @return the parsed Item, or null if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid Item
+     */
+    default StructuredFields.Item getStructuredItem(String name)
+    {
+        String value = getStructuredValue(name, (f, n) -> f.is(n));
+        return value == null ? null : StructuredFields.parseItem(value);
+    }
+
+    /**
+     * Get the combined values of the fields with the given header
+     * as a {@link StructuredFields} List.
+     *
+     * @param header the header
+     * @return the parsed List, empty if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid List
+     */
+    default List<StructuredFields.Member> getStructuredList(HttpHeader header)
+    {
+        String value = getStructuredValue(header, (f, h) -> f.getHeader() == h);
+        return value == null ? Collections.emptyList() : StructuredFields.parseList(value);
+    }
+
+    /**
+     * Get the combined values of the fields with the given name
+     * as a {@link StructuredFields} List.
+     *
+     * @param name the case-insensitive field name
+     * @return the parsed List, empty if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid List
+     */
+    default List<StructuredFields.Member> getStructuredList(String name)
+    {
+        String value = getStructuredValue(name, (f, n) -> f.is(n));
+        return value == null ? Collections.emptyList() : StructuredFields.parseList(value);
+    }
+
+    /**
+     * Get the combined values of the fields with the given header
+     * as a {@link StructuredFields} Dictionary.
+     *
+     * @param header the header
+     * @return the parsed Dictionary, empty if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid Dictionary
+     */
+    default Map<String, StructuredFields.Member> getStructuredDictionary(HttpHeader header)
+    {
+        String value = getStructuredValue(header, (f, h) -> f.getHeader() == h);
+        return value == null ? Collections.emptyMap() : StructuredFields.parseDictionary(value);
+    }
+
+    /**
+     * Get the combined values of the fields with the given name
+     * as a {@link StructuredFields} Dictionary.
+     *
+     * @param name the case-insensitive field name
+     * @return the parsed Dictionary, empty if the field is not present
+     * @throws IllegalArgumentException if the field value is not a valid Dictionary
+     */
+    default Map<String, StructuredFields.Member> getStructuredDictionary(String name)
+    {
+        String value = getStructuredValue(name, (f, n) -> f.is(n));
+        return value == null ? Collections.emptyMap() : StructuredFields.parseDictionary(value);
+    }
+
+    private <T> String getStructuredValue(T header, BiPredicate<HttpField, T> predicate)
+    {
+        // Multiple field lines are combined before parsing, see RFC 8941 section 4.2.
+        StringBuilder builder = null;
+        for (HttpField f : this)
+        {
+            if (predicate.test(f, header) && f.getValue() != null)
+            {
+                if (builder == null)
+                    builder = new StringBuilder(f.getValue());
+                else
+                    builder.append(", ").append(f.getValue());
+            }
+        }
+        return builder == null ? null : builder.toString();
+    }
+
     /**
      * Get multi headers
      *
@@ -36,6 +36,7 @@ import static org.eclipse.jetty.http.HttpCompliance.Violation;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.CASE_SENSITIVE_FIELD_NAME;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.DUPLICATE_HOST_HEADERS;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.HTTP_0_9;
+import static org.eclipse.jetty.http.HttpCompliance.Violation.INVALID_CHUNK_EXTENSION;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.MULTIPLE_CONTENT_LENGTHS;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.NO_COLON_AFTER_FIELD_NAME;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.TRANSFER_ENCODING_WITH_CONTENT_LENGTH;
@@ -210,6 +211,25 @@ public class HttpParser
         CLOSED  // The associated stream/endpoint is at EOF
     }
 
+    private enum ChunkExtensionState
+    {
+        SIZE,
+        NAME_START,
+        NAME,
+        NAME_END,
+        VALUE_START,
+        TOKEN_VALUE,
+        QUOTED_VALUE,
+        QUOTED_PAIR,
+        QUOTED_VALUE_END,
+        VALUE_END;
+
+        private boolean isComplete()
+        {
+            return this == NAME || this == TOKEN_VALUE || this == QUOTED_VALUE_END;
+        }
+    }
+
     private static final EnumSet<State> __idleStates = EnumSet.of(State.START, State.END, State.CLOSE, State.CLOSED);
     private static final EnumSet<State> __completeStates = EnumSet.of(State.END, State.CLOSE, State.CLOSED);
     private static final EnumSet<State> __terminatedStates = EnumSet.of(State.CLOSE, State.CLOSED);
@@ -244,6 +264,7 @@ public class HttpParser
     private long _contentPosition;
     private int _chunkLength;
     private int _chunkPosition;
+    private ChunkExtensionState _chunkExtensionState;
     private boolean _headResponse;
     private boolean _cr;
     private ByteBuffer _contentChunk;
@@ -330,7 +351,7 @@ public class HttpParser
         if (violation.isAllowedBy(_complianceMode))
             reportComplianceViolation(violation, violation.getDescription());
         else
-            throw new BadMessageException(HttpStatus.BAD_REQUEST_400, violation.getDescription());
+            rejectComplianceViolation(violation, violation.getDescription());
     }
 
     protected void reportComplianceViolation(Violation violation)
@@ -341,7 +362,35 @@ public class HttpParser
     protected void reportComplianceViolation(Violation violation, String reason)
     {
         if (_complianceListener != null)
-            _complianceListener.onComplianceViolation(_complianceMode, violation, reason);
+            _complianceListener.onComplianceViolation(new ComplianceViolation.Event(_complianceMode, violation, reason, true));
+    }
+
+    /**
+     * <p>Reports a violation that is not allowed by the compliance mode, and rejects the message.</p>
+     *
+     * @param violation the violation
+     * @param reason the reason of the violation
+     * @throws BadMessageException always
+     */
+    protected void rejectComplianceViolation(Violation violation, String reason) throws BadMessageException
+    {
+        rejectComplianceViolation(violation, reason, HttpStatus.BAD_REQUEST_400);
+    }
+
+    private void rejectComplianceViolation(Violation violation, String reason, int status) throws BadMessageException
+    {
+        if (_complianceListener != null)
+        {
+            try
+            {
+                _complianceListener.onComplianceViolation(new ComplianceViolation.Event(_complianceMode, violation, reason, false));
+            }
+            catch (Throwable x)
+            {
+                LOG.info("failure notifying listener {}", _complianceListener, x);
+            }
+        }
+        throw new BadMessageException(status, violation.getDescription());
     }
 
     protected String caseInsensitiveHeader(String orig, String normative)
@@ -782,7 +831,7 @@ public class HttpParser
                             }
                             else
                             {
-                                throw new BadMessageException(HttpStatus.HTTP_VERSION_NOT_SUPPORTED_505, "HTTP/0.9 not supported");
+                                rejectComplianceViolation(HTTP_0_9, HTTP_0_9.getDescription(), HttpStatus.HTTP_VERSION_NOT_SUPPORTED_505);
                             }
                             break;
 
@@ -974,7 +1023,7 @@ public class HttpParser
                         {
                             checkViolation(MULTIPLE_CONTENT_LENGTHS);
                             if (contentLength != _contentLength)
-                                throw new BadMessageException(HttpStatus.BAD_REQUEST_400, MULTIPLE_CONTENT_LENGTHS.getDescription());
+                                rejectComplianceViolation(MULTIPLE_CONTENT_LENGTHS, "Different values " + _contentLength + " and " + contentLength);
                         }
                         _hasContentLength = true;
 
@@ -1782,7 +1831,7 @@ public class HttpParser
                             break;
 
                         case SPACE:
-                            setState(State.CHUNK_PARAMS);
+                            startChunkExtension(t);
                             break;
 
                         default:
@@ -1794,7 +1843,7 @@ public class HttpParser
                             }
                             else
                             {
-                                setState(State.CHUNK_PARAMS);
+                                startChunkExtension(t);
                             }
                     }
                     break;
@@ -1809,6 +1858,9 @@ public class HttpParser
                     switch (t.getType())
                     {
                         case LF:
+                            if (_chunkExtensionState != null && !_chunkExtensionState.isComplete())
+                                invalidChunkExtension();
+                            _chunkExtensionState = null;
                             if (_chunkLength == 0)
                             {
                                 setState(State.TRAILER);
@@ -1819,7 +1871,8 @@ public class HttpParser
                                 setState(State.CHUNK);
                             break;
                         default:
-                            break; // TODO review
+                            parseChunkExtension(t);
+                            break;
                     }
                     break;
                 }
@@ -1863,6 +1916,107 @@ public class HttpParser
         return false;
     }
 
+    private void startChunkExtension(HttpTokens.Token t)
+    {
+        setState(State.CHUNK_PARAMS);
+        _chunkExtensionState = ChunkExtensionState.SIZE;
+        parseChunkExtension(t);
+    }
+
+    /**
+     * <p>Validates, without buffering it, the chunk extension grammar:</p>
+     * <pre>
+     * chunk-ext = *( BWS ";" BWS chunk-ext-name [ BWS "=" BWS chunk-ext-val ] )
+     * chunk-ext-name = token
+     * chunk-ext-val = token / quoted-string
+     * </pre>
+     */
+    private void parseChunkExtension(HttpTokens.Token t)
+    {
+        ChunkExtensionState state = _chunkExtensionState;
+        if (state == null)
+            return;
+
+        boolean whiteSpace = t.getType() == HttpTokens.Type.SPACE || t.getType() == HttpTokens.Type.HTAB;
+        boolean tchar = t.getType() == HttpTokens.Type.ALPHA || t.getType() == HttpTokens.Type.DIGIT || t.getType() == HttpTokens.Type.TCHAR;
+        char c = t.getChar();
+        switch (state)
+        {
+            case SIZE:
+            case NAME_END:
+            case VALUE_END:
+                if (c == ';')
+                    state = ChunkExtensionState.NAME_START;
+                else if (c == '=' && state == ChunkExtensionState.NAME_END)
+                    state = ChunkExtensionState.VALUE_START;
+                else if (!whiteSpace)
+                    state = null;
+                break;
+            case NAME_START:
+                if (tchar)
+                    state = ChunkExtensionState.NAME;
+                else if (!whiteSpace)
+                    state = null;
+                break;
+            case NAME:
+                if (c == ';')
+                    state = ChunkExtensionState.NAME_START;
+                else if (c == '=')
+                    state = ChunkExtensionState.VALUE_START;
+                else if (whiteSpace)
+                    state = ChunkExtensionState.NAME_END;
+                else if (!tchar)
+                    state = null;
+                break;
+            case VALUE_START:
+                if (tchar)
+                    state = ChunkExtensionState.TOKEN_VALUE;
+                else if (c == '"')
+                    state = ChunkExtensionState.QUOTED_VALUE;
+                else if (!whiteSpace)
+                    state = null;
+                break;
+            case TOKEN_VALUE:
+                if (c == ';')
+                    state = ChunkExtensionState.NAME_START;
+                else if (whiteSpace)
+                    state = ChunkExtensionState.VALUE_END;
+                else if (!tchar)
+                    state = null;
+                break;
+            case QUOTED_VALUE:
+                if (c == '"')
+                    state = ChunkExtensionState.QUOTED_VALUE_END;
+                else if (c == '\\')
+                    state = ChunkExtensionState.QUOTED_PAIR;
+                break;
+            case QUOTED_PAIR:
+                state = ChunkExtensionState.QUOTED_VALUE;
+                break;
+            case QUOTED_VALUE_END:
+                if (c == ';')
+                    state = ChunkExtensionState.NAME_START;
+                else if (whiteSpace)
+                    state = ChunkExtensionState.VALUE_END;
+                else
+                    state = null;
+                break;
+            default:
+                throw new IllegalStateException();
+        }
+
+        _chunkExtensionState = state;
+        if (state == null)
+            invalidChunkExtension();
+    }
+
+    private void invalidChunkExtension()
+    {
+        // Report the violation only once per chunk, and ignore the rest of the chunk extension if allowed.
+        _chunkExtensionState = null;
+        checkViolation(INVALID_CHUNK_EXTENSION);
+    }
+
     public boolean isAtEOF()
     {
         return _eof;
@@ -15,7 +15,9 @@ package org.eclipse.jetty.http;
 
 import java.util.ArrayList;
 import java.util.Iterator;
+import java.util.LinkedHashMap;
 import java.util.List;
+import java.util.Map;
 import java.util.Objects;
 import java.util.function.ToIntFunction;
 import java.util.stream.Collectors;
@@ -173,6 +175,24 @@ public class QuotedQualityCSV extends QuotedCSV implements Iterable<String>
         return _values.iterator();
     }
 
+    /**
+     * <p>Returns the values mapped to their quality, in the order they were parsed.</p>
+     * <p>Unlike {@link #getValues()}, the returned map includes the values with quality zero,
+     * that explicitly mark a value as not acceptable. If a value is repeated, its first
+     * quality is retained.</p>
+     *
+     * @return the values mapped to their quality
+     */
+    public Map<String, Double> getQualities()
+    {
+        Map<String, Double> qualities = new LinkedHashMap<>();
+        for (QualityValue qv : _qualities)
+        {
+            qualities.putIfAbsent(qv._value, qv._quality);
+        }
+        return qualities;
+    }
+
     protected void sort()
     {
         _values.clear();
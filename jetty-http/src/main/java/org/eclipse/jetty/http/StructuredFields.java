@@ -0,0 +1,896 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.math.BigDecimal;
+import java.math.RoundingMode;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Base64;
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+
+/**
+ * <p>Parser and serializer of
+ * <a href="https://datatracker.ietf.org/doc/html/rfc8941">RFC 8941</a> Structured Field Values.</p>
+ * <p>A structured field value is either an {@link Item}, a List of {@link Member}s or
+ * a Dictionary of {@link Member}s, where a member is either an {@link Item} or an
+ * {@link InnerList}; items and inner lists may have parameters.</p>
+ * <p>Bare item values are represented by the following Java types:</p>
+ * <ul>
+ * <li>Integer: {@link Long}</li>
+ * <li>Decimal: {@link BigDecimal}</li>
+ * <li>String: {@link String}</li>
+ * <li>Token: {@link Token}</li>
+ * <li>Byte Sequence: {@code byte[]}</li>
+ * <li>Boolean: {@link Boolean}</li>
+ * </ul>
+ * <p>Parsing methods throw {@link IllegalArgumentException} if the field value
+ * is not valid; per the specification, the whole field should then be ignored.</p>
+ *
+ * @see HttpFields#getStructuredItem(String)
+ * @see HttpFields#getStructuredList(String)
+ * @see HttpFields#getStructuredDictionary(String)
+ */
+public final class StructuredFields
+{
+    private static final long MAX_INTEGER = 999_999_999_999_999L;
+    private static final BigDecimal MAX_DECIMAL = new BigDecimal("999999999999.999");
+
+    private StructuredFields()
+    {
+    }
+
+    /**
+     * @param value the field value
+     * @return the parsed Item
+     * @throws IllegalArgumentException if the field value is not a valid Item
+     */
+    public static Item parseItem(String value)
+    {
+        Parser parser = new Parser(value);
+        Item item = parser.parseItem();
+        parser.end();
+        return item;
+    }
+
+    /**
+     * @param value the field value
+     * @return the parsed List, possibly empty
+     * @throws IllegalArgumentException if the field value is not a valid List
+     */
+    public static List<Member> parseList(String value)
+    {
+        Parser parser = new Parser(value);
+        List<Member> list = parser.parseList();
+        parser.end();
+        return list;
+    }
+
+    /**
+     * @param value the field value
+     * @return the parsed Dictionary, possibly empty, in field order
+     * @throws IllegalArgumentException if the field value is not a valid Dictionary
+     */
+    public static Map<String, Member> parseDictionary(String value)
+    {
+        Parser parser = new Parser(value);
+        Map<String, Member> dictionary = parser.parseDictionary();
+        parser.end();
+        return dictionary;
+    }
+
+    /**
+     * @param item the Item to serialize
+     * @return the serialized field value
+     * @throws IllegalArgumentException if the Item cannot be serialized
+     */
+    public static String serialize(Item item)
+    {
+        StringBuilder builder = new StringBuilder();
+        serializeItem(builder, item);
+        return builder.toString();
+    }
+
+    /**
+     * @param list the List to serialize
+     * @return the serialized field value
+     * @throws IllegalArgumentException if the List cannot be serialized
+     */
+    public static String serialize(List<? extends Member> list)
+    {
+        StringBuilder builder = new StringBuilder();
+        for (Member member : list)
+        {
+            if (builder.length() > 0)
+                builder.append(", ");
+            serializeMember(builder, member);
+        }
+        return builder.toString();
+    }
+
+    /**
+     * @param dictionary the Dictionary to serialize
+     * @return the serialized field value
+     * @throws IllegalArgumentException if the Dictionary cannot be serialized
+     */
+    public static String serialize(Map<String, ? extends Member> dictionary)
+    {
+        StringBuilder builder = new StringBuilder();
+        for (Map.Entry<String, ? extends Member> entry : dictionary.entrySet())
+        {
+            if (builder.length() > 0)
+                builder.append(", ");
+            serializeKey(builder, entry.getKey());
+            Member member = entry.getValue();
+            if (member instanceof Item && Boolean.TRUE.equals(((Item)member).getValue()))
+            {
+                serializeParameters(builder, member.getParameters());
+            }
+            else
+            {
+                builder.append('=');
+                serializeMember(builder, member);
+            }
+        }
+        return builder.toString();
+    }
+
+    private static void serializeMember(StringBuilder builder, Member member)
+    {
+        if (member instanceof InnerList)
+        {
+            builder.append('(');
+            List<Item> items = ((InnerList)member).getItems();
+            for (int i = 0; i < items.size(); ++i)
+            {
+                if (i > 0)
+                    builder.append(' ');
+                serializeItem(builder, items.get(i));
+            }
+            builder.append(')');
+            serializeParameters(builder, member.getParameters());
+        }
+        else
+        {
+            serializeItem(builder, (Item)member);
+        }
+    }
+
+    private static void serializeItem(StringBuilder builder, Item item)
+    {
+        serializeBareItem(builder, item.getValue());
+        serializeParameters(builder, item.getParameters());
+    }
+
+    private static void serializeParameters(StringBuilder builder, Map<String, Object> parameters)
+    {
+        for (Map.Entry<String, Object> entry : parameters.entrySet())
+        {
+            builder.append(';');
+            serializeKey(builder, entry.getKey());
+            Object value = entry.getValue();
+            if (!Boolean.TRUE.equals(value))
+            {
+                builder.append('=');
+                serializeBareItem(builder, value);
+            }
+        }
+    }
+
+    private static void serializeKey(StringBuilder builder, String key)
+    {
+        if (!isKey(key))
+            throw new IllegalArgumentException("Invalid key: " + key);
+        builder.append(key);
+    }
+
+    private static void serializeBareItem(StringBuilder builder, Object value)
+    {
+        if (value instanceof Long)
+        {
+            long integer = (Long)value;
+            if (integer < -MAX_INTEGER || integer > MAX_INTEGER)
+                throw new IllegalArgumentException("Integer out of range: " + integer);
+            builder.append(integer);
+        }
+        else if (value instanceof BigDecimal)
+        {
+            BigDecimal decimal = ((BigDecimal)value).setScale(3, RoundingMode.HALF_EVEN);
+            if (decimal.abs().compareTo(MAX_DECIMAL) > 0)
+                throw new IllegalArgumentException("Decimal out of range: " + value);
+            decimal = decimal.stripTrailingZeros();
+            if (decimal.scale() < 1)
+                decimal = decimal.setScale(1, RoundingMode.UNNECESSARY);
+            builder.append(decimal.toPlainString());
+        }
+        else if (value instanceof String)
+        {
+            String string = (String)value;
+            builder.append('"');
+            for (int i = 0; i < string.length(); ++i)
+            {
+                char c = string.charAt(i);
+                if (c < 0x20 || c > 0x7E)
+                    throw new IllegalArgumentException("Invalid string character 0x" + Integer.toHexString(c));
+                if (c == '"' || c == '\\')
+                    builder.append('\\');
+                builder.append(c);
+            }
+            builder.append('"');
+        }
+        else if (value instanceof Token)
+        {
+            builder.append(((Token)value).getValue());
+        }
+        else if (value instanceof byte[])
+        {
+            builder.append(':').append(Base64.getEncoder().encodeToString((byte[])value)).append(':');
+        }
+        else if (value instanceof Boolean)
+        {
+            builder.append((Boolean)value ? "?1" : "?0");
+        }
+        else
+        {
+            throw new IllegalArgumentException("Invalid bare item " + value);
+        }
+    }
+
+    private static Object normalize(Object value)
+    {
+        if (value instanceof Integer || value instanceof Short || value instanceof Byte)
+            return ((Number)value).longValue();
+        if (value instanceof Double || value instanceof Float)
+            return BigDecimal.valueOf(((Number)value).doubleValue());
+        if (value instanceof Long || value instanceof BigDecimal || value instanceof String ||
+            value instanceof Token || value instanceof byte[] || value instanceof Boolean)
+            return value;
+        throw new IllegalArgumentException("Invalid bare item " + value);
+    }
+
+    private static boolean isKey(String key)
+    {
+        if (key == null || key.isEmpty())
+            return false;
+        char first = key.charAt(0);
+        if (!isLowerAlpha(first) && first != '*')
+            return false;
+        for (int i = 1; i < key.length(); ++i)
+        {
+            if (!isKeyChar(key.charAt(i)))
+                return false;
+        }
+        return true;
+    }
+
+    private static boolean isKeyChar(char c)
+    {
+        return isLowerAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*';
+    }
+
+    private static boolean isLowerAlpha(char c)
+    {
+        return c >= 'a' && c <= 'z';
+    }
+
+    private static boolean isAlpha(char c)
+    {
+        return isLowerAlpha(c) || c >= 'A' && c <= 'Z';
+    }
+
+    private static boolean isDigit(char c)
+    {
+        return c >= '0' && c <= '9';
+    }
+
+    private static boolean isTokenChar(char c)
+    {
+        if (isAlpha(c) || isDigit(c))
+            return true;
+        switch (c)
+        {
+            case '!':
+            case '#':
+            case '$':
+            case '%':
+            case '&':
+            case '\'':
+            case '*':
+            case '+':
+            case '-':
+            case '.':
+            case '^':
+            case '_':
+            case '`':
+            case '|':
+            case '~':
+            case ':':
+            case '/':
+                return true;
+            default:
+                return false;
+        }
+    }
+
+    private static boolean isBase64Char(char c)
+    {
+        return isAlpha(c) || isDigit(c) || c == '+' || c == '/' || c == '=';
+    }
+
+    private static Map<String, Object> newParameters(Map<String, ?> parameters)
+    {
+        if (parameters.isEmpty())
+            return Collections.emptyMap();
+        Map<String, Object> result = new LinkedHashMap<>();
+        for (Map.Entry<String, ?> entry : parameters.entrySet())
+        {
+            if (!isKey(entry.getKey()))
+                throw new IllegalArgumentException("Invalid key: " + entry.getKey());
+            result.put(entry.getKey(), normalize(Objects.requireNonNull(entry.getValue())));
+        }
+        return Collections.unmodifiableMap(result);
+    }
+
+    private static boolean parametersEqual(Map<String, Object> p1, Map<String, Object> p2)
+    {
+        if (!p1.keySet().equals(p2.keySet()))
+            return false;
+        for (Map.Entry<String, Object> entry : p1.entrySet())
+        {
+            if (!Objects.deepEquals(entry.getValue(), p2.get(entry.getKey())))
+                return false;
+        }
+        return true;
+    }
+
+    /**
+     * <p>A member of a List or of a Dictionary, either an {@link Item} or an {@link InnerList}.</p>
+     */
+    public interface Member
+    {
+        /**
+         * @return the ordered, unmodifiable, parameters of this member
+         */
+        Map<String, Object> getParameters();
+    }
+
+    /**
+     * <p>A bare item value with parameters.</p>
+     */
+    public static class Item implements Member
+    {
+        private final Object _value;
+        private final Map<String, Object> _parameters;
+
+        public Item(Object value)
+        {
+            this(value, Collections.emptyMap());
+        }
+
+        /**
+         * @param value the bare item value
+         * @param parameters the parameters, whose values are bare item values
+         * @throws IllegalArgumentException if the value or a parameter value is not a valid bare item
+         */
+        public Item(Object value, Map<String, ?> parameters)
+        {
+            _value = normalize(Objects.requireNonNull(value));
+            _parameters = newParameters(parameters);
+        }
+
+        /**
+         * @return the bare item value
+         */
+        public Object getValue()
+        {
+            return _value;
+        }
+
+        @Override
+        public Map<String, Object> getParameters()
+        {
+            return _parameters;
+        }
+
+        /**
+         * @return the Integer value
+         * @throws ClassCastException if the value is not an Integer
+         */
+        public long asLong()
+        {
+            return (Long)_value;
+        }
+
+        /**
+         * @return the Integer or Decimal value
+         * @throws ClassCastException if the value is not an Integer or a Decimal
+         */
+        public BigDecimal asDecimal()
+        {
+            if (_value instanceof Long)
+                return BigDecimal.valueOf((Long)_value);
+            return (BigDecimal)_value;
+        }
+
+        /**
+         * @return the String or Token value
+         * @throws ClassCastException if the value is not a String or a Token
+         */
+        public String asString()
+        {
+            if (_value instanceof Token)
+                return ((Token)_value).getValue();
+            return (String)_value;
+        }
+
+        /**
+         * @return the Byte Sequence value
+         * @throws ClassCastException if the value is not a Byte Sequence
+         */
+        public byte[] asBytes()
+        {
+            return (byte[])_value;
+        }
+
+        /**
+         * @return the Boolean value
+         * @throws ClassCastException if the value is not a Boolean
+         */
+        public boolean asBoolean()
+        {
+            return (Boolean)_value;
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            if (this == obj)
+                return true;
+            if (!(obj instanceof Item))
+                return false;
+            Item that = (Item)obj;
+            return Objects.deepEquals(_value, that._value) && parametersEqual(_parameters, that._parameters);
+        }
+
+        @Override
+        public int hashCode()
+        {
+            int hash = _value instanceof byte[] ? Arrays.hashCode((byte[])_value) : _value.hashCode();
+            return 31 * hash + _parameters.keySet().hashCode();
+        }
+
+        @Override
+        public String toString()
+        {
+            return serialize(this);
+        }
+    }
+
+    /**
+     * <p>An ordered list of {@link Item}s, with parameters.</p>
+     */
+    public static class InnerList implements Member
+    {
+        private final List<Item> _items;
+        private final Map<String, Object> _parameters;
+
+        public InnerList(List<Item> items)
+        {
+            this(items, Collections.emptyMap());
+        }
+
+        /**
+         * @param items the items of this inner list
+         * @param parameters the parameters, whose values are bare item values
+         * @throws IllegalArgumentException if a parameter value is not a valid bare item
+         */
+        public InnerList(List<Item> items, Map<String, ?> parameters)
+        {
+            _items = List.copyOf(items);
+            _parameters = newParameters(parameters);
+        }
+
+        /**
+         * @return the unmodifiable items of this inner list
+         */
+        public List<Item> getItems()
+        {
+            return _items;
+        }
+
+        @Override
+        public Map<String, Object> getParameters()
+        {
+            return _parameters;
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            if (this == obj)
+                return true;
+            if (!(obj instanceof InnerList))
+                return false;
+            InnerList that = (InnerList)obj;
+            return _items.equals(that._items) && parametersEqual(_parameters, that._parameters);
+        }
+
+        @Override
+        public int hashCode()
+        {
+            return 31 * _items.hashCode() + _parameters.keySet().hashCode();
+        }
+
+        @Override
+        public String toString()
+        {
+            return serialize(List.of(this));
+        }
+    }
+
+    /**
+     * <p>A Token bare item value, distinct from a String bare item value.</p>
+     */
+    public static class Token
+    {
+        private final String _value;
+
+        /**
+         * @param value the token value
+         * @throws IllegalArgumentException if the value is not a valid token
+         */
+        public Token(String value)
+        {
+            if (value == null || value.isEmpty() || !isAlpha(value.charAt(0)) && value.charAt(0) != '*')
+                throw new IllegalArgumentException("Invalid token: " + value);
+            for (int i = 1; i < value.length(); ++i)
+            {
+                if (!isTokenChar(value.charAt(i)))
+                    throw new IllegalArgumentException("Invalid token: " + value);
+            }
+            _value = value;
+        }
+
+        public String getValue()
+        {
+            return _value;
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            return obj instanceof Token && _value.equals(((Token)obj)._value);
+        }
+
+        @Override
+        public int hashCode()
+        {
+            return _value.hashCode();
+        }
+
+        @Override
+        public String toString()
+        {
+            return _value;
+        }
+    }
+
+    private static class Parser
+    {
+        private final String _input;
+        private int _index;
+
+        private Parser(String input)
+        {
+            _input = Objects.requireNonNull(input);
+            skipSpaces();
+        }
+
+        private void end()
+        {
+            skipSpaces();
+            if (!isEnd())
+                throw failure("Unexpected character");
+        }
+
+        private List<Member> parseList()
+        {
+            List<Member> list = new ArrayList<>();
+            while (!isEnd())
+            {
+                list.add(parseMember());
+                skipWhiteSpaces();
+                if (isEnd())
+                    break;
+                expect(',');
+                skipWhiteSpaces();
+                if (isEnd())
+                    throw failure("Trailing comma");
+            }
+            return list;
+        }
+
+        private Map<String, Member> parseDictionary()
+        {
+            Map<String, Member> dictionary = new LinkedHashMap<>();
+            while (!isEnd())
+            {
+                String key = parseKey();
+                Member member;
+                if (peek() == '=')
+                {
+                    ++_index;
+                    member = parseMember();
+                }
+                else
+                {
+                    member = new Item(Boolean.TRUE, parseParameters());
+                }
+                dictionary.put(key, member);
+                skipWhiteSpaces();
+                if (isEnd())
+                    break;
+                expect(',');
+                skipWhiteSpaces();
+                if (isEnd())
+                    throw failure("Trailing comma");
+            }
+            return dictionary;
+        }
+
+        private Member parseMember()
+        {
+            if (peek() == '(')
+                return parseInnerList();
+            return parseItem();
+        }
+
+        private InnerList parseInnerList()
+        {
+            expect('(');
+            List<Item> items = new ArrayList<>();
+            while (!isEnd())
+            {
+                skipSpaces();
+                if (peek() == ')')
+                {
+                    ++_index;
+                    return new InnerList(items, parseParameters());
+                }
+                items.add(parseItem());
+                char c = peek();
+                if (c != ' ' && c != ')')
+                    throw failure("Invalid inner list");
+            }
+            throw failure("Unterminated inner list");
+        }
+
+        private Item parseItem()
+        {
+            Object value = parseBareItem();
+            return new Item(value, parseParameters());
+        }
+
+        private Map<String, Object> parseParameters()
+        {
+            Map<String, Object> parameters = null;
+            while (peek() == ';')
+            {
+                ++_index;
+                skipSpaces();
+                String key = parseKey();
+                Object value = Boolean.TRUE;
+                if (peek() == '=')
+                {
+                    ++_index;
+                    value = parseBareItem();
+                }
+                if (parameters == null)
+                    parameters = new LinkedHashMap<>();
+                parameters.put(key, value);
+            }
+            return parameters == null ? Collections.emptyMap() : parameters;
+        }
+
+        private String parseKey()
+        {
+            int start = _index;
+            char c = peek();
+            if (!isLowerAlpha(c) && c != '*')
+                throw failure("Invalid key");
+            ++_index;
+            while (!isEnd() && isKeyChar(peek()))
+            {
+                ++_index;
+            }
+            return _input.substring(start, _index);
+        }
+
+        private Object parseBareItem()
+        {
+            char c = peek();
+            if (c == '-' || isDigit(c))
+                return parseNumber();
+            if (c == '"')
+                return parseString();
+            if (c == '*' || isAlpha(c))
+                return parseToken();
+            if (c == ':')
+                return parseBytes();
+            if (c == '?')
+                return parseBoolean();
+            throw failure("Invalid bare item");
+        }
+
+        private Object parseNumber()
+        {
+            int start = _index;
+            if (peek() == '-')
+                ++_index;
+            if (!isDigit(peek()))
+                throw failure("Invalid number");
+            int digits = 0;
+            int dot = -1;
+            while (!isEnd())
+            {
+                char c = peek();
+                if (isDigit(c))
+                {
+                    ++digits;
+                }
+                else if (c == '.' && dot < 0)
+                {
+                    if (digits > 12)
+                        throw failure("Decimal too long");
+                    dot = _index;
+                }
+                else
+                {
+                    break;
+                }
+                ++_index;
+                if (dot < 0 && digits > 15)
+                    throw failure("Integer too long");
+                if (dot >= 0 && digits > 15)
+                    throw failure("Decimal too long");
+            }
+            String number = _input.substring(start, _index);
+            if (dot < 0)
+                return Long.parseLong(number);
+            int fraction = _index - dot - 1;
+            if (fraction == 0 || fraction > 3)
+                throw failure("Invalid decimal");
+            return new BigDecimal(number);
+        }
+
+        private String parseString()
+        {
+            expect('"');
+            StringBuilder builder = new StringBuilder();
+            while (!isEnd())
+            {
+                char c = _input.charAt(_index++);
+                if (c == '\\')
+                {
+                    if (isEnd())
+                        throw failure("Invalid string escape");
+                    c = _input.charAt(_index++);
+                    if (c != '"' && c != '\\')
+                        throw failure("Invalid string escape");
+                    builder.append(c);
+                }
+                else if (c == '"')
+                {
+                    return builder.toString();
+                }
+                else if (c < 0x20 || c > 0x7E)
+                {
+                    throw failure("Invalid string character");
+                }
+                else
+                {
+                    builder.append(c);
+                }
+            }
+            throw failure("Unterminated string");
+        }
+
+        private Token parseToken()
+        {
+            int start = _index++;
+            while (!isEnd() && isTokenChar(peek()))
+            {
+                ++_index;
+            }
+            return new Token(_input.substring(start, _index));
+        }
+
+        private byte[] parseBytes()
+        {
+            expect(':');
+            int start = _index;
+            while (!isEnd() && peek() != ':')
+            {
+                if (!isBase64Char(peek()))
+                    throw failure("Invalid byte sequence");
+                ++_index;
+            }
+            if (isEnd())
+                throw failure("Unterminated byte sequence");
+            String base64 = _input.substring(start, _index++);
+            try
+            {
+                return Base64.getDecoder().decode(base64);
+            }
+            catch (IllegalArgumentException x)
+            {
+                throw failure("Invalid byte sequence");
+            }
+        }
+
+        private Boolean parseBoolean()
+        {
+            expect('?');
+            char c = peek();
+            if (c != '0' && c != '1')
+                throw failure("Invalid boolean");
+            ++_index;
+            return c == '1';
+        }
+
+        private boolean isEnd()
+        {
+            return _index >= _input.length();
+        }
+
+        private char peek()
+        {
+            return isEnd() ? 0 : _input.charAt(_index);
+        }
+
+        private void expect(char c)
+        {
+            if (peek() != c)
+                throw failure("Expected '" + c + "'");
+            ++_index;
+        }
+
+        private void skipSpaces()
+        {
+            while (peek() == ' ')
+            {
+                ++_index;
+            }
+        }
+
+        private void skipWhiteSpaces()
+        {
+            char c = peek();
+            while (c == ' ' || c == '\t')
+            {
+                ++_index;
+                c = peek();
+            }
+        }
+
+        private IllegalArgumentException failure(String message)
+        {
+            return new IllegalArgumentException(message + " at index " + _index + " in: " + _input);
+        }
+    }
+}
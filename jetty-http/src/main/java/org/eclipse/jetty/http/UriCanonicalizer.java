@@ -0,0 +1,660 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.EnumMap;
+import java.util.EnumSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+
+import org.eclipse.jetty.util.URIUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Canonicalizes request URI paths following
+ * <a href="https://datatracker.ietf.org/doc/html/rfc3986#section-6.2.2">RFC 3986 section 6.2.2</a>,
+ * so that all the components that interpret a path (request handling, dispatching, rewriting and
+ * proxying) agree on its meaning.</p>
+ * <p>Canonicalization of an encoded path:</p>
+ * <ul>
+ * <li>decodes, preserves or rejects each percent-encoded character according to the {@link Decoding}
+ * of its {@link CharacterClass}, normalizing preserved encodings to upper case hexadecimal digits;</li>
+ * <li>rejects overlong and otherwise invalid UTF-8 encodings, as well as the non standard {@code %uXXXX} encodings;</li>
+ * <li>handles raw and encoded backslashes according to the {@link Backslash} policy;</li>
+ * <li>detects double encodings such as {@code %252e}, which reveal a different path if decoded twice;</li>
+ * <li>removes the {@code .} and {@code ..} segments, rejecting paths that would escape the root;</li>
+ * <li>rejects paths whose decoded form contains dot segments that are not in the canonical encoded form,
+ * for example {@code /a/..%2Fb} or {@code /a/..;p/b}.</li>
+ * </ul>
+ * <p>Each {@link Violation} found is reported to the {@link ComplianceViolation.Listener listeners} as an
+ * {@link ComplianceViolation.Event}, whether or not the current configuration allows it; violations that are
+ * not allowed cause a {@link BadMessageException} to be thrown.</p>
+ * <p>Instances should be configured before use and may then be shared by multiple threads.</p>
+ */
+public class UriCanonicalizer implements ComplianceViolation.Mode
+{
+    private static final Logger LOG = LoggerFactory.getLogger(UriCanonicalizer.class);
+    private static final String REPLACEMENT = "%EF%BF%BD";
+
+    /**
+     * The violations that may be found while canonicalizing a path.
+     */
+    public enum Violation implements ComplianceViolation
+    {
+        /**
+         * Dot segments e.g. <code>/foo/../bar</code>, allowed unless {@link #setAllowDotSegments(boolean)} is false.
+         */
+        DOT_SEGMENT("https://datatracker.ietf.org/doc/html/rfc3986#section-5.2.4", "Dot segment"),
+        /**
+         * Dot segments that only appear once the path is decoded e.g. <code>/foo/..%2Fbar</code>, never allowed.
+         */
+        DECODED_DOT_SEGMENT("https://datatracker.ietf.org/doc/html/rfc3986#section-3.3", "Decoded dot segment"),
+        /**
+         * A percent-encoded character whose {@link CharacterClass} is {@link Decoding#REJECT rejected}.
+         */
+        ENCODED_CHARACTER("https://datatracker.ietf.org/doc/html/rfc3986#section-2.1", "Rejected encoded character"),
+        /**
+         * Overlong UTF-8 encodings e.g. <code>%C0%AE</code>, never allowed.
+         */
+        OVERLONG_UTF8("https://datatracker.ietf.org/doc/html/rfc3629#section-3", "Overlong UTF-8 encoding"),
+        /**
+         * Invalid UTF-8 encodings, including surrogates and code points above U+10FFFF, never allowed.
+         */
+        INVALID_UTF8("https://datatracker.ietf.org/doc/html/rfc3629#section-3", "Invalid UTF-8 encoding"),
+        /**
+         * Raw or encoded backslashes, allowed unless the {@link Backslash} policy is {@link Backslash#REJECT}.
+         */
+        BACKSLASH("https://datatracker.ietf.org/doc/html/rfc3986#section-3.3", "Backslash in path"),
+        /**
+         * Double encodings e.g. <code>%252e</code>, allowed only if {@link #setAllowDoubleEncoding(boolean)} is true.
+         */
+        DOUBLE_ENCODING("https://datatracker.ietf.org/doc/html/rfc3986#section-2.4", "Double encoding");
+
+        private final String _url;
+        private final String _description;
+
+        Violation(String url, String description)
+        {
+            _url = url;
+            _description = description;
+        }
+
+        @Override
+        public String getName()
+        {
+            return name();
+        }
+
+        @Override
+        public String getURL()
+        {
+            return _url;
+        }
+
+        @Override
+        public String getDescription()
+        {
+            return _description;
+        }
+    }
+
+    /**
+     * The classes of percent-encoded characters.
+     */
+    public enum CharacterClass
+    {
+        /**
+         * Letters, digits and {@code - . _ ~}, decoded by default.
+         */
+        UNRESERVED,
+        /**
+         * The path separator {@code /}, preserved by default.
+         */
+        SEPARATOR,
+        /**
+         * The percent character {@code %}, preserved by default; it cannot be decoded.
+         */
+        PERCENT,
+        /**
+         * The delimiters {@code : ? # [ ] @ ! $ & ' ( ) * + , ; =}, preserved by default.
+         */
+        RESERVED,
+        /**
+         * The other printable US-ASCII characters, such as space, {@code "} or {@code |}, preserved by default.
+         */
+        OTHER,
+        /**
+         * The US-ASCII control characters, rejected by default.
+         */
+        CONTROL,
+        /**
+         * The characters encoded as multi-byte UTF-8 sequences, preserved by default.
+         */
+        NON_ASCII
+    }
+
+    /**
+     * How the percent-encoded characters of a {@link CharacterClass} are canonicalized.
+     */
+    public enum Decoding
+    {
+        /**
+         * The character is decoded in the canonical path.
+         */
+        DECODE,
+        /**
+         * The character stays percent-encoded in the canonical path.
+         */
+        PRESERVE,
+        /**
+         * The path is rejected with a {@link Violation#ENCODED_CHARACTER} violation.
+         */
+        REJECT
+    }
+
+    /**
+     * How raw and encoded backslashes are canonicalized.
+     */
+    public enum Backslash
+    {
+        /**
+         * The backslash is canonicalized as the encoded character {@code %5C}.
+         */
+        PRESERVE,
+        /**
+         * The backslash is canonicalized as a path separator.
+         */
+        SEPARATOR,
+        /**
+         * The path is rejected with a {@link Violation#BACKSLASH} violation.
+         */
+        REJECT
+    }
+
+    /**
+     * The result of the canonicalization of a path.
+     */
+    public static class CanonicalPath
+    {
+        private final String _path;
+        private final String _decodedPath;
+
+        private CanonicalPath(String path, String decodedPath)
+        {
+            _path = path;
+            _decodedPath = decodedPath;
+        }
+
+        /**
+         * @return the canonical encoded path, including any path parameters
+         */
+        public String getPath()
+        {
+            return _path;
+        }
+
+        /**
+         * @return the decoded canonical path, without path parameters
+         */
+        public String getDecodedPath()
+        {
+            return _decodedPath;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s,%s}", getClass().getSimpleName(), hashCode(), _path, _decodedPath);
+        }
+    }
+
+    private final Map<CharacterClass, Decoding> _decodings = new EnumMap<>(CharacterClass.class);
+    private final List<ComplianceViolation.Listener> _listeners = new CopyOnWriteArrayList<>();
+    private Backslash _backslash = Backslash.REJECT;
+    private boolean _allowDotSegments = true;
+    private boolean _allowDoubleEncoding;
+
+    public UriCanonicalizer()
+    {
+        for (CharacterClass characterClass : CharacterClass.values())
+        {
+            _decodings.put(characterClass, Decoding.PRESERVE);
+        }
+        _decodings.put(CharacterClass.UNRESERVED, Decoding.DECODE);
+        _decodings.put(CharacterClass.CONTROL, Decoding.REJECT);
+    }
+
+    /**
+     * @param characterClass the class of percent-encoded characters
+     * @return how the percent-encoded characters of the given class are canonicalized
+     */
+    public Decoding getDecoding(CharacterClass characterClass)
+    {
+        return _decodings.get(characterClass);
+    }
+
+    /**
+     * @param characterClass the class of percent-encoded characters
+     * @param decoding how the percent-encoded characters of the given class are canonicalized
+     */
+    public void setDecoding(CharacterClass characterClass, Decoding decoding)
+    {
+        Objects.requireNonNull(decoding);
+        if (characterClass == CharacterClass.PERCENT && decoding == Decoding.DECODE)
+            throw new IllegalArgumentException("Cannot decode " + characterClass);
+        _decodings.put(characterClass, decoding);
+    }
+
+    public Backslash getBackslash()
+    {
+        return _backslash;
+    }
+
+    public void setBackslash(Backslash backslash)
+    {
+        _backslash = Objects.requireNonNull(backslash);
+    }
+
+    public boolean isAllowDotSegments()
+    {
+        return _allowDotSegments;
+    }
+
+    /**
+     * @param allowDotSegments whether dot segments are removed (the default) rather than rejected
+     */
+    public void setAllowDotSegments(boolean allowDotSegments)
+    {
+        _allowDotSegments = allowDotSegments;
+    }
+
+    public boolean isAllowDoubleEncoding()
+    {
+        return _allowDoubleEncoding;
+    }
+
+    /**
+     * @param allowDoubleEncoding whether double encodings are allowed rather than rejected (the default)
+     */
+    public void setAllowDoubleEncoding(boolean allowDoubleEncoding)
+    {
+        _allowDoubleEncoding = allowDoubleEncoding;
+    }
+
+    /**
+     * @param listener the listener notified of every violation found by this canonicalizer
+     */
+    public void addListener(ComplianceViolation.Listener listener)
+    {
+        _listeners.add(listener);
+    }
+
+    public boolean removeListener(ComplianceViolation.Listener listener)
+    {
+        return _listeners.remove(listener);
+    }
+
+    @Override
+    public String getName()
+    {
+        return "CANONICAL";
+    }
+
+    @Override
+    public boolean allows(ComplianceViolation violation)
+    {
+        if (violation == Violation.DOT_SEGMENT)
+            return _allowDotSegments;
+        if (violation == Violation.BACKSLASH)
+            return _backslash != Backslash.REJECT;
+        if (violation == Violation.DOUBLE_ENCODING)
+            return _allowDoubleEncoding;
+        return false;
+    }
+
+    @Override
+    public Set<Violation> getKnown()
+    {
+        return Collections.unmodifiableSet(EnumSet.allOf(Violation.class));
+    }
+
+    @Override
+    public Set<Violation> getAllowed()
+    {
+        Set<Violation> allowed = EnumSet.noneOf(Violation.class);
+        for (Violation violation : Violation.values())
+        {
+            if (allows(violation))
+                allowed.add(violation);
+        }
+        return Collections.unmodifiableSet(allowed);
+    }
+
+    /**
+     * @param path the encoded absolute path to canonicalize, without query
+     * @return the canonical path
+     * @throws BadMessageException if the path is invalid or has a violation that is not allowed
+     * @see #canonicalize(String, ComplianceViolation.Listener)
+     */
+    public CanonicalPath canonicalize(String path) throws BadMessageException
+    {
+        return canonicalize(path, null);
+    }
+
+    /**
+     * @param path the encoded absolute path to canonicalize, without query
+     * @param listener a listener notified of the violations in addition to the listeners of this canonicalizer, or null
+     * @return the canonical path
+     * @throws BadMessageException if the path is invalid or has a violation that is not allowed
+     */
+    public CanonicalPath canonicalize(String path, ComplianceViolation.Listener listener) throws BadMessageException
+    {
+        if (path == null || !path.startsWith("/"))
+            throw new BadMessageException("Bad URI");
+
+        StringBuilder canonical = new StringBuilder(path.length());
+        int length = path.length();
+        for (int i = 0; i < length; ++i)
+        {
+            char c = path.charAt(i);
+            if (c == '\\')
+            {
+                appendBackslash(canonical, path, listener);
+                continue;
+            }
+            if (c != '%')
+            {
+                canonical.append(c);
+                continue;
+            }
+
+            int b = hexByte(path, i);
+            i += 2;
+            if (b >= 0x80)
+            {
+                i = appendUtf8(canonical, path, i, b, listener);
+                continue;
+            }
+
+            if (b == '\\')
+            {
+                appendBackslash(canonical, path, listener);
+                continue;
+            }
+
+            CharacterClass characterClass = classify(b);
+            if (characterClass == CharacterClass.PERCENT && isDoubleEncoding(path, i + 1))
+                notify(Violation.DOUBLE_ENCODING, path, listener);
+
+            switch (_decodings.get(characterClass))
+            {
+                case DECODE:
+                    canonical.append((char)b);
+                    break;
+                case PRESERVE:
+                    appendEncoded(canonical, b);
+                    break;
+                default:
+                    throw reject(Violation.ENCODED_CHARACTER, path, listener);
+            }
+        }
+
+        String canonicalPath = removeDotSegments(canonical.toString(), path, listener);
+        String decodedPath = URIUtil.decodePath(canonicalPath);
+        if (hasDotSegment(decodedPath))
+            throw reject(Violation.DECODED_DOT_SEGMENT, path, listener);
+        return new CanonicalPath(canonicalPath, decodedPath);
+    }
+
+    private void appendBackslash(StringBuilder canonical, String path, ComplianceViolation.Listener listener)
+    {
+        notify(Violation.BACKSLASH, path, listener);
+        if (_backslash == Backslash.SEPARATOR)
+            canonical.append('/');
+        else
+            canonical.append("%5C");
+    }
+
+    private int appendUtf8(StringBuilder canonical, String path, int index, int lead, ComplianceViolation.Listener listener)
+    {
+        int count;
+        int codePoint;
+        if (lead >= 0xC0 && lead <= 0xDF)
+        {
+            count = 1;
+            codePoint = lead & 0x1F;
+        }
+        else if (lead >= 0xE0 && lead <= 0xEF)
+        {
+            count = 2;
+            codePoint = lead & 0x0F;
+        }
+        else if (lead >= 0xF0 && lead <= 0xF7)
+        {
+            count = 3;
+            codePoint = lead & 0x07;
+        }
+        else
+        {
+            // A continuation byte, or the lead byte of a 5 or 6 byte sequence.
+            throw reject(Violation.INVALID_UTF8, path, listener);
+        }
+
+        int[] bytes = new int[count + 1];
+        bytes[0] = lead;
+        for (int i = 1; i <= count; ++i)
+        {
+            if (index + 1 >= path.length() || path.charAt(index + 1) != '%')
+                throw reject(Violation.INVALID_UTF8, path, listener);
+            int b = hexByte(path, index + 1);
+            if ((b & 0xC0) != 0x80)
+                throw reject(Violation.INVALID_UTF8, path, listener);
+            bytes[i] = b;
+            codePoint = (codePoint << 6) | (b & 0x3F);
+            index += 3;
+        }
+
+        int minimum = count == 1 ? 0x80 : count == 2 ? 0x800 : 0x10000;
+        if (codePoint < minimum)
+            throw reject(Violation.OVERLONG_UTF8, path, listener);
+        if (codePoint > 0x10FFFF || (codePoint >= 0xD800 && codePoint <= 0xDFFF))
+            throw reject(Violation.INVALID_UTF8, path, listener);
+
+        switch (_decodings.get(CharacterClass.NON_ASCII))
+        {
+            case DECODE:
+                canonical.appendCodePoint(codePoint);
+                break;
+            case PRESERVE:
+                for (int b : bytes)
+                {
+                    appendEncoded(canonical, b);
+                }
+                break;
+            default:
+                throw reject(Violation.ENCODED_CHARACTER, path, listener);
+        }
+        return index;
+    }
+
+    private static int hexByte(String path, int index)
+    {
+        if (index + 2 >= path.length())
+            throw new BadMessageException("Bad URI encoding");
+        int high = hexDigit(path.charAt(index + 1));
+        int low = hexDigit(path.charAt(index + 2));
+        if (high < 0 || low < 0)
+            throw new BadMessageException("Bad URI encoding");
+        return high << 4 | low;
+    }
+
+    private static int hexDigit(int c)
+    {
+        if (c >= '0' && c <= '9')
+            return c - '0';
+        if (c >= 'a' && c <= 'f')
+            return c - 'a' + 10;
+        if (c >= 'A' && c <= 'F')
+            return c - 'A' + 10;
+        return -1;
+    }
+
+    private static boolean isDoubleEncoding(String path, int index)
+    {
+        // Whether the 2 characters following an encoded '%', themselves possibly encoded, are hex digits.
+        for (int digit = 0; digit < 2; ++digit)
+        {
+            if (index >= path.length())
+                return false;
+            int c = path.charAt(index);
+            if (c == '%')
+            {
+                try
+                {
+                    c = hexByte(path, index);
+                }
+                catch (BadMessageException x)
+                {
+                    return false;
+                }
+                index += 3;
+            }
+            else
+            {
+                ++index;
+            }
+            if (hexDigit(c) < 0)
+                return false;
+        }
+        return true;
+    }
+
+    private static CharacterClass classify(int b)
+    {
+        if (b < 0x20 || b == 0x7F)
+            return CharacterClass.CONTROL;
+        if ((b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || "-._~".indexOf(b) >= 0)
+            return CharacterClass.UNRESERVED;
+        if (b == '/')
+            return CharacterClass.SEPARATOR;
+        if (b == '%')
+            return CharacterClass.PERCENT;
+        if (":?#[]@!$&'()*+,;=".indexOf(b) >= 0)
+            return CharacterClass.RESERVED;
+        return CharacterClass.OTHER;
+    }
+
+    private static void appendEncoded(StringBuilder canonical, int b)
+    {
+        canonical.append('%');
+        canonical.append(Character.toUpperCase(Character.forDigit(b >> 4, 16)));
+        canonical.append(Character.toUpperCase(Character.forDigit(b & 0x0F, 16)));
+    }
+
+    private String removeDotSegments(String canonical, String path, ComplianceViolation.Listener listener)
+    {
+        // The canonical path starts with '/', so the first segment is empty.
+        String[] segments = canonical.split("/", -1);
+        List<String> output = new ArrayList<>(segments.length);
+        boolean dotSegments = false;
+        for (int i = 1; i < segments.length; ++i)
+        {
+            String segment = segments[i];
+            boolean last = i == segments.length - 1;
+            if (".".equals(segment))
+            {
+                dotSegments = true;
+                if (last)
+                    output.add("");
+            }
+            else if ("..".equals(segment))
+            {
+                dotSegments = true;
+                if (output.isEmpty())
+                    throw new BadMessageException("Bad URI");
+                output.remove(output.size() - 1);
+                if (last)
+                    output.add("");
+            }
+            else
+            {
+                output.add(segment);
+            }
+        }
+        if (!dotSegments)
+            return canonical;
+
+        notify(Violation.DOT_SEGMENT, path, listener);
+        return "/" + String.join("/", output);
+    }
+
+    private static boolean hasDotSegment(String decoded)
+    {
+        for (String segment : decoded.split("/", -1))
+        {
+            if (".".equals(segment) || "..".equals(segment))
+                return true;
+        }
+        return false;
+    }
+
+    private void notify(Violation violation, String path, ComplianceViolation.Listener listener)
+    {
+        if (allows(violation))
+            notify(new ComplianceViolation.Event(this, violation, path, true), listener);
+        else
+            throw reject(violation, path, listener);
+    }
+
+    private BadMessageException reject(Violation violation, String path, ComplianceViolation.Listener listener)
+    {
+        notify(new ComplianceViolation.Event(this, violation, path, false), listener);
+        return new BadMessageException(violation.getDescription());
+    }
+
+    private void notify(ComplianceViolation.Event event, ComplianceViolation.Listener listener)
+    {
+        if (listener != null)
+            notify(listener, event);
+        for (ComplianceViolation.Listener l : _listeners)
+        {
+            notify(l, event);
+        }
+    }
+
+    private static void notify(ComplianceViolation.Listener listener, ComplianceViolation.Event event)
+    {
+        try
+        {
+            listener.onComplianceViolation(event);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{decodings=%s,backslash=%s,dotSegments=%b,doubleEncoding=%b}",
+            getClass().getSimpleName(), hashCode(), _decodings, _backslash, _allowDotSegments, _allowDoubleEncoding);
+    }
+}
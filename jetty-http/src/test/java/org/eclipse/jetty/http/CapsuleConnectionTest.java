@@ -0,0 +1,137 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.io.ByteArrayEndPoint;
+import org.eclipse.jetty.io.MappedByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class CapsuleConnectionTest
+{
+    private final List<String> capsules = new ArrayList<>();
+    private final CompletableFuture<Throwable> closed = new CompletableFuture<>();
+    private final ByteArrayEndPoint endPoint = new ByteArrayEndPoint()
+    {
+        @Override
+        public void onClose(Throwable failure)
+        {
+            super.onClose(failure);
+            // Notify the connection, like the selector and the tunnel endpoints do.
+            getConnection().onClose(failure);
+        }
+    };
+    private final CapsuleConnection connection = new CapsuleConnection(endPoint, Runnable::run, new MappedByteBufferPool(), new CapsuleConnection.Listener()
+    {
+        @Override
+        public void onCapsule(long type, ByteBuffer value)
+        {
+            capsules.add(type + ":" + BufferUtil.toString(value, StandardCharsets.UTF_8));
+        }
+
+        @Override
+        public void onClose(Throwable failure)
+        {
+            closed.complete(failure);
+        }
+    }, 1024);
+
+    private void open()
+    {
+        endPoint.setConnection(connection);
+        connection.onOpen();
+    }
+
+    @Test
+    public void testReceiveCapsules() throws Exception
+    {
+        open();
+
+        ByteBuffer capsule = CapsuleGenerator.generate(0x2A, BufferUtil.toBuffer("hello"));
+        ByteBuffer first = capsule.slice();
+        first.limit(3);
+        capsule.position(3);
+        endPoint.addInput(first);
+        endPoint.addInput(capsule);
+        endPoint.addInput(CapsuleGenerator.generateDatagram(0, BufferUtil.toBuffer("udp")));
+        assertEquals(List.of("42:hello", "0:\u0000udp"), capsules);
+
+        endPoint.addInputEOF();
+        assertNull(closed.get(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testTruncatedCapsule() throws Exception
+    {
+        open();
+
+        ByteBuffer capsule = CapsuleGenerator.generate(0x2A, BufferUtil.toBuffer("hello"));
+        capsule.limit(capsule.limit() - 1);
+        endPoint.addInput(capsule);
+        endPoint.addInputEOF();
+
+        assertThat(closed.get(5, TimeUnit.SECONDS), instanceOf(IOException.class));
+        assertTrue(capsules.isEmpty());
+    }
+
+    @Test
+    public void testSendCapsules() throws Exception
+    {
+        open();
+
+        Callback.Completable first = new Callback.Completable();
+        connection.send(0x2A, BufferUtil.toBuffer("hello"), first);
+        Callback.Completable second = new Callback.Completable();
+        connection.send(CapsuleGenerator.generateDatagram(0, BufferUtil.toBuffer("udp")), second);
+        first.get(5, TimeUnit.SECONDS);
+        second.get(5, TimeUnit.SECONDS);
+
+        List<String> sent = new ArrayList<>();
+        CapsuleParser parser = new CapsuleParser((type, value) -> sent.add(type + ":" + BufferUtil.toString(value, StandardCharsets.UTF_8)));
+        parser.parse(endPoint.takeOutput());
+        assertTrue(parser.isComplete());
+        assertEquals(List.of("42:hello", "0:\u0000udp"), sent);
+    }
+
+    @Test
+    public void testSendAfterClose() throws Exception
+    {
+        open();
+        endPoint.close();
+        assertNull(closed.get(5, TimeUnit.SECONDS));
+
+        Callback.Completable callback = new Callback.Completable();
+        connection.send(0x2A, BufferUtil.toBuffer("hello"), callback);
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> callback.get(5, TimeUnit.SECONDS));
+        assertThat(failure.getCause(), instanceOf(ClosedChannelException.class));
+    }
+}
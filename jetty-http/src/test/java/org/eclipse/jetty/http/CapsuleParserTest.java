@@ -0,0 +1,117 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.List;
+
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.StringUtil;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class CapsuleParserTest
+{
+    @Test
+    public void testGenerateVarInts()
+    {
+        // Test vectors from RFC 9000, appendix A.1.
+        assertArrayEquals(StringUtil.fromHexString("2500"), BufferUtil.toArray(CapsuleGenerator.generate(37, null)));
+        assertArrayEquals(StringUtil.fromHexString("7bbd00"), BufferUtil.toArray(CapsuleGenerator.generate(15293, null)));
+        assertArrayEquals(StringUtil.fromHexString("9d7f3e7d00"), BufferUtil.toArray(CapsuleGenerator.generate(494878333, null)));
+        assertArrayEquals(StringUtil.fromHexString("c2197c5eff14e88c00"), BufferUtil.toArray(CapsuleGenerator.generate(151288809941952652L, null)));
+    }
+
+    @Test
+    public void testParseFragmented()
+    {
+        List<Long> types = new ArrayList<>();
+        List<String> values = new ArrayList<>();
+        CapsuleParser parser = new CapsuleParser((type, value) ->
+        {
+            types.add(type);
+            values.add(BufferUtil.toString(value, StandardCharsets.UTF_8));
+        });
+
+        ByteBuffer first = CapsuleGenerator.generate(CapsuleParser.DATAGRAM, BufferUtil.toBuffer("hello"));
+        ByteBuffer second = CapsuleGenerator.generate(151288809941952652L, BufferUtil.toBuffer("world"));
+        ByteBuffer empty = CapsuleGenerator.generate(0x2A, BufferUtil.EMPTY_BUFFER);
+        ByteBuffer all = BufferUtil.allocate(first.remaining() + second.remaining() + empty.remaining());
+        BufferUtil.append(all, first);
+        BufferUtil.append(all, second);
+        BufferUtil.append(all, empty);
+
+        // Feed one byte at a time.
+        while (all.hasRemaining())
+        {
+            parser.parse(ByteBuffer.wrap(new byte[]{all.get()}));
+        }
+
+        assertTrue(parser.isComplete());
+        assertEquals(List.of(CapsuleParser.DATAGRAM, 151288809941952652L, 0x2AL), types);
+        assertEquals(List.of("hello", "world", ""), values);
+    }
+
+    @Test
+    public void testDatagramCapsule()
+    {
+        List<ByteBuffer> values = new ArrayList<>();
+        CapsuleParser parser = new CapsuleParser((type, value) ->
+        {
+            assertEquals(CapsuleParser.DATAGRAM, type);
+            values.add(value);
+        });
+
+        parser.parse(CapsuleGenerator.generateDatagram(0, BufferUtil.toBuffer("udp")));
+        parser.parse(CapsuleGenerator.generateDatagram(15293, null));
+
+        assertEquals(2, values.size());
+        ByteBuffer first = values.get(0);
+        assertEquals(0, CapsuleParser.decodeVarInt(first));
+        assertEquals("udp", BufferUtil.toString(first, StandardCharsets.UTF_8));
+        ByteBuffer second = values.get(1);
+        assertEquals(15293, CapsuleParser.decodeVarInt(second));
+        assertFalse(second.hasRemaining());
+
+        // Incomplete variable length integers are not consumed.
+        ByteBuffer incomplete = ByteBuffer.wrap(StringUtil.fromHexString("9d7f"));
+        assertEquals(-1, CapsuleParser.decodeVarInt(incomplete));
+        assertEquals(2, incomplete.remaining());
+    }
+
+    @Test
+    public void testTruncatedCapsule()
+    {
+        CapsuleParser parser = new CapsuleParser((type, value) -> {});
+        parser.parse(ByteBuffer.wrap(StringUtil.fromHexString("0005aabb")));
+        assertFalse(parser.isComplete());
+        parser.parse(ByteBuffer.wrap(StringUtil.fromHexString("ccddee")));
+        assertTrue(parser.isComplete());
+    }
+
+    @Test
+    public void testCapsuleTooLarge()
+    {
+        CapsuleParser parser = new CapsuleParser((type, value) -> {}, 16);
+        ByteBuffer capsule = CapsuleGenerator.generate(CapsuleParser.DATAGRAM, ByteBuffer.allocate(17));
+        assertThrows(IllegalArgumentException.class, () -> parser.parse(capsule));
+    }
+}
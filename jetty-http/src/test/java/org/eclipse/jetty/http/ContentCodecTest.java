@@ -0,0 +1,109 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.io.ByteArrayOutputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class ContentCodecTest
+{
+    private static final ContentCodec GZIP = ContentCodec.getContentCodec("gzip");
+    private static final ContentCodec OTHER = new ContentCodec("other")
+    {
+        @Override
+        public int getDefaultLevel()
+        {
+            return 0;
+        }
+
+        @Override
+        public OutputStream newEncoder(OutputStream output, int level)
+        {
+            return output;
+        }
+
+        @Override
+        public Decoder newDecoder(ByteBufferPool byteBufferPool)
+        {
+            return buffer -> buffer;
+        }
+    };
+
+    @Test
+    public void testGzipIsAlwaysAvailable()
+    {
+        assertThat(GZIP, notNullValue());
+        assertThat(ContentCodec.getContentCodecs().get(0), is(GZIP));
+    }
+
+    @Test
+    public void testNegotiate()
+    {
+        List<ContentCodec> codecs = List.of(OTHER, GZIP);
+
+        // Client preference wins.
+        assertThat(ContentCodec.negotiate(List.of("gzip, other;q=0.5"), codecs), is(GZIP));
+        // Server preference breaks ties.
+        assertThat(ContentCodec.negotiate(List.of("gzip, other"), codecs), is(OTHER));
+        assertThat(ContentCodec.negotiate(List.of("gzip", "other"), List.of(GZIP, OTHER)), is(GZIP));
+        // Wildcard and explicit exclusion.
+        assertThat(ContentCodec.negotiate(List.of("*;q=0.5, other;q=0"), codecs), is(GZIP));
+        assertThat(ContentCodec.negotiate(List.of("GZIP;q=0, *;q=0"), codecs), nullValue());
+        assertThat(ContentCodec.negotiate(List.of("identity"), codecs), nullValue());
+        assertThat(ContentCodec.negotiate(List.of(), codecs), nullValue());
+    }
+
+    @Test
+    public void testGzipEncodeDecode() throws Exception
+    {
+        String content = "Hello, Hello, Hello, Hello, Hello, Hello, Hello, World!";
+        ByteArrayOutputStream encoded = new ByteArrayOutputStream();
+        try (OutputStream encoder = GZIP.newEncoder(encoded, 9))
+        {
+            encoder.write(content.getBytes(StandardCharsets.UTF_8));
+        }
+
+        ContentCodec.Decoder decoder = GZIP.newDecoder(null);
+        ByteBuffer buffer = ByteBuffer.wrap(encoded.toByteArray());
+        StringBuilder decoded = new StringBuilder();
+        // Push the encoded bytes one at a time.
+        while (buffer.hasRemaining())
+        {
+            ByteBuffer slice = buffer.slice();
+            slice.limit(1);
+            buffer.position(buffer.position() + 1);
+            while (slice.hasRemaining())
+            {
+                ByteBuffer chunk = decoder.decode(slice);
+                decoded.append(BufferUtil.toUTF8String(chunk));
+                decoder.release(chunk);
+            }
+        }
+        decoder.destroy();
+
+        assertThat(decoded.toString(), is(content));
+    }
+}
@@ -0,0 +1,114 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.util.stream.Stream;
+
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.Arguments;
+import org.junit.jupiter.params.provider.MethodSource;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class ContentDispositionTest
+{
+    public static Stream<Arguments> generate()
+    {
+        return Stream.of(
+            Arguments.of(ContentDisposition.attachment(null), "attachment"),
+            Arguments.of(ContentDisposition.attachment("file.txt"), "attachment; filename=\"file.txt\""),
+            Arguments.of(ContentDisposition.inline("a \"b\" \\ c.txt"), "inline; filename=\"a \\\"b\\\" \\\\ c.txt\""),
+            Arguments.of(ContentDisposition.attachment("\u20AC rates.txt"), "attachment; filename=\"_ rates.txt\"; filename*=UTF-8''%E2%82%AC%20rates.txt"),
+            Arguments.of(ContentDisposition.attachment("a\r\nb'c.txt"), "attachment; filename=\"a__b'c.txt\"; filename*=UTF-8''a%0D%0Ab%27c.txt"),
+            Arguments.of(ContentDisposition.attachment("\uD83D\uDE00.png"), "attachment; filename=\"_.png\"; filename*=UTF-8''%F0%9F%98%80.png"),
+            Arguments.of(ContentDisposition.formData("field", null), "form-data; name=\"field\""),
+            Arguments.of(ContentDisposition.formData("f\"ile", "r\u00E9sum\u00E9\r\n.pdf"), "form-data; name=\"f%22ile\"; filename=\"r\u00E9sum\u00E9%0D%0A.pdf\""),
+            Arguments.of(new ContentDisposition("Attachment").parameter("Title", "\u00E9t\u00E9"), "attachment; title*=UTF-8''%C3%A9t%C3%A9")
+        );
+    }
+
+    @ParameterizedTest
+    @MethodSource("generate")
+    public void testGenerate(ContentDisposition disposition, String expected)
+    {
+        assertThat(disposition.asString(), is(expected));
+        // Generated values parse back to the same disposition, except form-data
+        // which uses an encoding that cannot be told apart from literal input.
+        if (!disposition.isFormData())
+            assertThat(ContentDisposition.parse(expected), is(disposition));
+    }
+
+    public static Stream<Arguments> parse()
+    {
+        return Stream.of(
+            Arguments.of("attachment", "attachment", null, null),
+            Arguments.of("ATTACHMENT ; FileName = file.txt ", "attachment", null, "file.txt"),
+            Arguments.of("inline; filename=\"file.txt\";", "inline", null, "file.txt"),
+            Arguments.of("form-data; name=\"field\"; filename=\"a;b.txt\"", "form-data", "field", "a;b.txt"),
+            Arguments.of("form-data; name=field; filename=\"Taken on Aug 22 \\ 2012.jpg\"", "form-data", "field", "Taken on Aug 22 \\ 2012.jpg"),
+            Arguments.of("form-data; name=\"file\"; filename=\"c:\\dir\\file.txt\"", "form-data", "file", "c:\\dir\\file.txt"),
+            Arguments.of("form-data; name=\"file\"; filename=\"c:\\\\dir\\\\file.txt\"", "form-data", "file", "c:\\dir\\file.txt"),
+            Arguments.of("form-data; name=\"file\"; filename=\"c:\\dir\\\"", "form-data", "file", "c:\\dir\\"),
+            Arguments.of("attachment; filename=\"a \\\"quoted\\\" name\"", "attachment", null, "a \"quoted\" name"),
+            // The extended parameter takes precedence, regardless of the order.
+            Arguments.of("attachment; filename*=UTF-8''%E2%82%AC%20rates.txt; filename=\"rates.txt\"", "attachment", null, "\u20AC rates.txt"),
+            Arguments.of("attachment; filename=\"rates.txt\"; filename*=utf-8'en'%E2%82%AC%20rates.txt", "attachment", null, "\u20AC rates.txt"),
+            Arguments.of("attachment; filename*=ISO-8859-1''%A3%20rates.txt", "attachment", null, "\u00A3 rates.txt"),
+            // Extended parameters that cannot be decoded are ignored.
+            Arguments.of("attachment; filename=\"rates.txt\"; filename*=UTF-8''%E2%82", "attachment", null, "rates.txt"),
+            Arguments.of("attachment; filename=\"rates.txt\"; filename*=UNKNOWN''rates", "attachment", null, "rates.txt"),
+            Arguments.of("attachment; filename=\"rates.txt\"; filename*=rates", "attachment", null, "rates.txt")
+        );
+    }
+
+    @ParameterizedTest
+    @MethodSource("parse")
+    public void testParse(String value, String type, String name, String fileName)
+    {
+        ContentDisposition disposition = ContentDisposition.parse(value);
+        assertThat(disposition.getType(), is(type));
+        assertThat(disposition.getName(), is(name));
+        assertThat(disposition.getFileName(), is(fileName));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {"", " ", "; filename=a", "attach ment", "attachment; filename=\"unterminated", "for/m; name=a"})
+    public void testParseInvalid(String value)
+    {
+        assertThrows(IllegalArgumentException.class, () -> ContentDisposition.parse(value));
+        assertThat(ContentDisposition.parse(value, null), nullValue());
+    }
+
+    @Test
+    public void testParameters()
+    {
+        ContentDisposition disposition = ContentDisposition.parse("attachment; filename=a.txt; size=10; creation-date=\"Wed, 12 Feb 1997 16:29:51 -0500\"");
+        assertThat(disposition.isAttachment(), is(true));
+        assertThat(disposition.getParameter("SIZE"), is("10"));
+        assertThat(disposition.getParameter("creation-date"), is("Wed, 12 Feb 1997 16:29:51 -0500"));
+        assertThat(String.join(",", disposition.getParameters().keySet()), is("filename,size,creation-date"));
+
+        disposition.parameter("size", null);
+        assertThat(disposition.asString(), is("attachment; filename=\"a.txt\"; creation-date=\"Wed, 12 Feb 1997 16:29:51 -0500\""));
+        assertThat(disposition.asHttpField().getHeader(), is(HttpHeader.CONTENT_DISPOSITION));
+
+        assertThrows(IllegalArgumentException.class, () -> disposition.parameter("filename*", "a.txt"));
+        assertThrows(IllegalArgumentException.class, () -> disposition.parameter("file name", "a.txt"));
+    }
+}
@@ -375,4 +375,70 @@ public class HttpCookieTest
         assertThat(HttpCookie.getCommentWithAttributes("__HTTP_ONLY____SAME_SITE_LAX__hello", true, HttpCookie.SameSite.LAX),
             is("hello__HTTP_ONLY____SAME_SITE_LAX__"));
     }
+
+    @Test
+    public void testPartitioned()
+    {
+        HttpCookie cookie = HttpCookie.build("name", "value")
+            .path("/")
+            .secure(true)
+            .sameSite(SameSite.NONE)
+            .partitioned(true)
+            .build();
+        assertEquals("name=value; Path=/; Secure; SameSite=None; Partitioned", cookie.getRFC6265SetCookie());
+
+        // Partitioned and SameSite are parsed from the Set-Cookie value.
+        HttpCookie parsed = new HttpCookie(cookie.getRFC6265SetCookie());
+        assertTrue(parsed.isPartitioned());
+        assertThat(parsed.getSameSite(), is(SameSite.NONE));
+        assertFalse(new HttpCookie("partitioned=value; Path=/").isPartitioned());
+
+        assertTrue(HttpCookie.isPartitionedInComment("comment__PARTITIONED__"));
+        assertThat(HttpCookie.getCommentWithoutAttributes("comment__PARTITIONED____HTTP_ONLY__"), is("comment"));
+        assertThat(HttpCookie.getCommentWithAttributes(null, false, null, true), is("__PARTITIONED__"));
+    }
+
+    @Test
+    public void testBuildFromCookie()
+    {
+        HttpCookie cookie = new HttpCookie("name", "value", "domain", "/path", 60, true, false, "comment", 1, SameSite.LAX);
+        HttpCookie copy = HttpCookie.build(cookie).secure(true).build();
+        assertThat(copy.getName(), is("name"));
+        assertThat(copy.getValue(), is("value"));
+        assertThat(copy.getDomain(), is("domain"));
+        assertThat(copy.getPath(), is("/path"));
+        assertThat(copy.getMaxAge(), is(60L));
+        assertTrue(copy.isHttpOnly());
+        assertTrue(copy.isSecure());
+        assertThat(copy.getComment(), is("comment"));
+        assertThat(copy.getVersion(), is(1));
+        assertThat(copy.getSameSite(), is(SameSite.LAX));
+        assertFalse(copy.isPartitioned());
+    }
+
+    @Test
+    public void testRequireValidPrefix()
+    {
+        HttpCookie.requireValidPrefix(new HttpCookie("name", "value"));
+        HttpCookie.requireValidPrefix(HttpCookie.build("__Secure-name", "value").secure(true).domain("example.com").build());
+        HttpCookie.requireValidPrefix(HttpCookie.build("__Host-name", "value").secure(true).path("/").build());
+
+        assertThrows(IllegalArgumentException.class, () -> HttpCookie.requireValidPrefix(new HttpCookie("__Secure-name", "value")));
+        assertThrows(IllegalArgumentException.class, () -> HttpCookie.requireValidPrefix(new HttpCookie("__host-name", "value")));
+        assertThrows(IllegalArgumentException.class, () -> HttpCookie.requireValidPrefix(HttpCookie.build("__Host-name", "value").secure(true).build()));
+        assertThrows(IllegalArgumentException.class, () -> HttpCookie.requireValidPrefix(HttpCookie.build("__Host-name", "value").secure(true).path("/").domain("example.com").build()));
+    }
+
+    @Test
+    public void testSecureAndPartitionedDefaults()
+    {
+        AttributesMap context = new AttributesMap();
+        assertNull(HttpCookie.getSecureDefault(context));
+        assertNull(HttpCookie.getPartitionedDefault(context));
+
+        context.setAttribute(HttpCookie.SECURE_DEFAULT_ATTRIBUTE, Boolean.TRUE);
+        context.setAttribute(HttpCookie.PARTITIONED_DEFAULT_ATTRIBUTE, "false");
+        assertThat(HttpCookie.getSecureDefault(context), is(true));
+        assertThat(HttpCookie.getPartitionedDefault(context), is(false));
+    }
 }
@@ -35,6 +35,7 @@ import org.junit.jupiter.params.provider.ValueSource;
 
 import static org.eclipse.jetty.http.HttpCompliance.Violation.CASE_INSENSITIVE_METHOD;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.CASE_SENSITIVE_FIELD_NAME;
+import static org.eclipse.jetty.http.HttpCompliance.Violation.INVALID_CHUNK_EXTENSION;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.MULTILINE_FIELD_VALUE;
 import static org.eclipse.jetty.http.HttpCompliance.Violation.TRANSFER_ENCODING_WITH_CONTENT_LENGTH;
 import static org.hamcrest.MatcherAssert.assertThat;
@@ -1883,6 +1884,79 @@ public class HttpParserTest
         assertThat(_complianceViolation, contains(TRANSFER_ENCODING_WITH_CONTENT_LENGTH));
     }
 
+    @Test
+    public void testParanoidRejectsTransferEncodingWithContentLength()
+    {
+        ByteBuffer buffer = BufferUtil.toBuffer(
+            "POST /chunk HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Transfer-Encoding: chunked\r\n" +
+                "Content-Length: 1\r\n" +
+                "\r\n" +
+                "1\r\n" +
+                "X\r\n" +
+                "0\r\n" +
+                "\r\n");
+
+        HttpParser.RequestHandler handler = new Handler();
+        HttpParser parser = new HttpParser(handler, HttpCompliance.PARANOID);
+        parseAll(parser, buffer);
+
+        assertEquals(TRANSFER_ENCODING_WITH_CONTENT_LENGTH.getDescription(), _bad);
+        assertThat(_complianceViolation, empty());
+        assertThat(_rejectedViolation, contains(TRANSFER_ENCODING_WITH_CONTENT_LENGTH));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {"1;\r\n", "1 \r\n", "1;=v\r\n", "1;a=\r\n", "1;a=b c\r\n", "1;a=\"b\r\n", "1x\r\n", "1;a,b\r\n"})
+    public void testInvalidChunkExtension(String chunkSize)
+    {
+        String request = "POST /chunk HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Transfer-Encoding: chunked\r\n" +
+            "\r\n" +
+            chunkSize +
+            "X\r\n" +
+            "0\r\n" +
+            "\r\n";
+
+        // Allowed by the default mode.
+        HttpParser parser = new HttpParser((HttpParser.RequestHandler)new Handler());
+        parseAll(parser, BufferUtil.toBuffer(request));
+        assertNull(_bad);
+        assertEquals("X", _content);
+        assertThat(_complianceViolation, contains(INVALID_CHUNK_EXTENSION));
+
+        init();
+        parser = new HttpParser((HttpParser.RequestHandler)new Handler(), HttpCompliance.PARANOID);
+        parseAll(parser, BufferUtil.toBuffer(request));
+        assertEquals(INVALID_CHUNK_EXTENSION.getDescription(), _bad);
+        assertThat(_rejectedViolation, contains(INVALID_CHUNK_EXTENSION));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {"1;a\r\n", "1 ; a\r\n", "1;a=b\r\n", "1;a = b;c\r\n", "1;a=\"b \\\" c\"\r\n", "1;a=\"\";b=c\r\n"})
+    public void testValidChunkExtension(String chunkSize)
+    {
+        ByteBuffer buffer = BufferUtil.toBuffer(
+            "POST /chunk HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Transfer-Encoding: chunked\r\n" +
+                "\r\n" +
+                chunkSize +
+                "X\r\n" +
+                "0\r\n" +
+                "\r\n");
+
+        HttpParser parser = new HttpParser((HttpParser.RequestHandler)new Handler(), HttpCompliance.PARANOID);
+        parseAll(parser, buffer);
+
+        assertNull(_bad);
+        assertEquals("X", _content);
+        assertTrue(_messageCompleted);
+        assertThat(_complianceViolation, empty());
+    }
+
     @Test
     public void testHost()
     {
@@ -2981,6 +3055,7 @@ public class HttpParserTest
         _contentCompleted = false;
         _messageCompleted = false;
         _complianceViolation.clear();
+        _rejectedViolation.clear();
     }
 
     private String _host;
@@ -3000,6 +3075,7 @@ public class HttpParserTest
     private boolean _contentCompleted;
     private boolean _messageCompleted;
     private final List<ComplianceViolation> _complianceViolation = new ArrayList<>();
+    private final List<ComplianceViolation> _rejectedViolation = new ArrayList<>();
 
     private class Handler implements HttpParser.RequestHandler, HttpParser.ResponseHandler, ComplianceViolation.Listener
     {
@@ -3106,6 +3182,14 @@ public class HttpParserTest
         {
             _complianceViolation.add(violation);
         }
+
+        @Override
+        public void onComplianceViolation(ComplianceViolation.Event event)
+        {
+            if (!event.isAllowed())
+                _rejectedViolation.add(event.getViolation());
+            ComplianceViolation.Listener.super.onComplianceViolation(event);
+        }
     }
 
     @Test
@@ -15,6 +15,7 @@ package org.eclipse.jetty.http;
 
 import java.util.ArrayList;
 import java.util.List;
+import java.util.Map;
 
 import org.hamcrest.Matchers;
 import org.junit.jupiter.api.Test;
@@ -52,6 +53,20 @@ public class QuotedQualityCSVTest
         assertThat(values, Matchers.contains("text/*", "text/plain", "text/plain;format=flowed", "*/*"));
     }
 
+    @Test
+    public void testQualities()
+    {
+        QuotedQualityCSV values = new QuotedQualityCSV();
+        values.addValue("gzip;q=0.5, identity;q=0, br, gzip;q=0.1");
+        assertThat(values, Matchers.contains("br", "gzip", "gzip"));
+
+        Map<String, Double> qualities = values.getQualities();
+        assertThat(qualities.keySet(), Matchers.contains("gzip", "identity", "br"));
+        assertThat(qualities.get("gzip"), Matchers.is(0.5D));
+        assertThat(qualities.get("identity"), Matchers.is(0.0D));
+        assertThat(qualities.get("br"), Matchers.is(1.0D));
+    }
+
     @Test
     public void test7231532Example3MostSpecific()
     {
@@ -0,0 +1,180 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.math.BigDecimal;
+import java.nio.charset.StandardCharsets;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class StructuredFieldsTest
+{
+    @Test
+    public void testBareItems()
+    {
+        assertEquals(42L, StructuredFields.parseItem("42").asLong());
+        assertEquals(-42L, StructuredFields.parseItem("-42").asLong());
+        assertEquals(new BigDecimal("4.5"), StructuredFields.parseItem("4.5").asDecimal());
+        assertEquals("he said \"hi\"", StructuredFields.parseItem("\"he said \\\"hi\\\"\"").asString());
+        assertEquals(new StructuredFields.Token("text/html"), StructuredFields.parseItem("text/html").getValue());
+        assertArrayEquals("hello".getBytes(StandardCharsets.US_ASCII), StructuredFields.parseItem(":aGVsbG8=:").asBytes());
+        assertTrue(StructuredFields.parseItem("?1").asBoolean());
+        assertFalse(StructuredFields.parseItem("?0").asBoolean());
+    }
+
+    @Test
+    public void testItemParameters()
+    {
+        StructuredFields.Item item = StructuredFields.parseItem("  abc;a=1;b=2;cde_456  ");
+        assertEquals("abc", item.asString());
+        Map<String, Object> parameters = item.getParameters();
+        assertEquals(List.of("a", "b", "cde_456"), List.copyOf(parameters.keySet()));
+        assertEquals(1L, parameters.get("a"));
+        assertEquals(Boolean.TRUE, parameters.get("cde_456"));
+        assertEquals("abc;a=1;b=2;cde_456", StructuredFields.serialize(item));
+    }
+
+    @Test
+    public void testList()
+    {
+        List<StructuredFields.Member> list = StructuredFields.parseList("sugar, tea;x=\"y\",  (\"foo\" \"bar\");lvl=5, ()");
+        assertEquals(4, list.size());
+        assertEquals("sugar", ((StructuredFields.Item)list.get(0)).asString());
+        assertEquals("y", list.get(1).getParameters().get("x"));
+        assertThat(list.get(2), instanceOf(StructuredFields.InnerList.class));
+        StructuredFields.InnerList innerList = (StructuredFields.InnerList)list.get(2);
+        assertEquals(2, innerList.getItems().size());
+        assertEquals(5L, innerList.getParameters().get("lvl"));
+        assertTrue(((StructuredFields.InnerList)list.get(3)).getItems().isEmpty());
+        assertEquals("sugar, tea;x=\"y\", (\"foo\" \"bar\");lvl=5, ()", StructuredFields.serialize(list));
+
+        assertTrue(StructuredFields.parseList("").isEmpty());
+    }
+
+    @Test
+    public void testDictionary()
+    {
+        // The Priority header, RFC 9218.
+        Map<String, StructuredFields.Member> dictionary = StructuredFields.parseDictionary("u=3, i");
+        assertEquals(3L, ((StructuredFields.Item)dictionary.get("u")).asLong());
+        assertTrue(((StructuredFields.Item)dictionary.get("i")).asBoolean());
+        assertEquals("u=3, i", StructuredFields.serialize(dictionary));
+
+        // Duplicate keys overwrite the previous value.
+        dictionary = StructuredFields.parseDictionary("a=1, b=2;x, a=(1 2)");
+        assertEquals(List.of("a", "b"), List.copyOf(dictionary.keySet()));
+        assertThat(dictionary.get("a"), instanceOf(StructuredFields.InnerList.class));
+        assertEquals("a=(1 2), b=2;x", StructuredFields.serialize(dictionary));
+    }
+
+    @Test
+    public void testSerializeDecimal()
+    {
+        assertEquals("1.0", StructuredFields.serialize(new StructuredFields.Item(1.0D)));
+        assertEquals("0.125", StructuredFields.serialize(new StructuredFields.Item(new BigDecimal("0.125"))));
+        assertEquals("0.112", StructuredFields.serialize(new StructuredFields.Item(new BigDecimal("0.1125"))));
+        assertEquals("-2.5", StructuredFields.serialize(new StructuredFields.Item(new BigDecimal("-2.500"))));
+        assertThrows(IllegalArgumentException.class, () -> StructuredFields.serialize(new StructuredFields.Item(new BigDecimal("1000000000000"))));
+    }
+
+    @Test
+    public void testSerialize()
+    {
+        Map<String, Object> parameters = new LinkedHashMap<>();
+        parameters.put("q", new BigDecimal("0.5"));
+        parameters.put("secure", true);
+        parameters.put("b", new byte[]{1, 2, 3});
+        StructuredFields.Item item = new StructuredFields.Item("value", parameters);
+        String serialized = StructuredFields.serialize(item);
+        assertEquals("\"value\";q=0.5;secure;b=:AQID:", serialized);
+        assertEquals(item, StructuredFields.parseItem(serialized));
+
+        assertThrows(IllegalArgumentException.class, () -> StructuredFields.serialize(new StructuredFields.Item("\u00e8")));
+        assertThrows(IllegalArgumentException.class, () -> StructuredFields.serialize(new StructuredFields.Item(1_000_000_000_000_000L)));
+        assertThrows(IllegalArgumentException.class, () -> new StructuredFields.Item(1, Map.of("Upper", 1)));
+        assertThrows(IllegalArgumentException.class, () -> new StructuredFields.Token("1abc"));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {
+        "",
+        "a, b",
+        "\"unterminated",
+        "\"bad \\escape\"",
+        "1234567890123456",
+        "1234567890123.5",
+        "1.2345",
+        "1.",
+        "?2",
+        ":bad_base64:",
+        ":aGVsbG8=",
+        "abc;",
+        "abc;A=1",
+        "(1 2",
+        "\u00e8"
+    })
+    public void testInvalidItem(String value)
+    {
+        assertThrows(IllegalArgumentException.class, () -> StructuredFields.parseItem(value));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {
+        "a,",
+        "a,,b",
+        "(1 2)(3)",
+        "a b"
+    })
+    public void testInvalidList(String value)
+    {
+        assertThrows(IllegalArgumentException.class, () -> StructuredFields.parseList(value));
+    }
+
+    @Test
+    public void testHttpFields()
+    {
+        HttpFields fields = HttpFields.build()
+            .add("Example-Dict", "a=1")
+            .add("Example-Dict", "b;x=?0")
+            .add("Example-List", "1, 2")
+            .add("example-list", "3")
+            .add(HttpHeader.CONTENT_LENGTH, "10");
+
+        Map<String, StructuredFields.Member> dictionary = fields.getStructuredDictionary("example-dict");
+        assertEquals(List.of("a", "b"), List.copyOf(dictionary.keySet()));
+        assertEquals(Boolean.FALSE, dictionary.get("b").getParameters().get("x"));
+
+        assertEquals(3, fields.getStructuredList("Example-List").size());
+        assertEquals(10L, fields.getStructuredItem(HttpHeader.CONTENT_LENGTH).asLong());
+
+        assertThat(fields.getStructuredItem("Missing"), nullValue());
+        assertThat(fields.getStructuredList(HttpHeader.CACHE_CONTROL).isEmpty(), is(true));
+        assertThrows(IllegalArgumentException.class, () -> fields.getStructuredItem("Example-List"));
+    }
+}
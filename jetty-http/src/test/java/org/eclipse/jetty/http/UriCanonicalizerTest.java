@@ -0,0 +1,178 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http;
+
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.stream.Stream;
+
+import org.eclipse.jetty.http.UriCanonicalizer.Backslash;
+import org.eclipse.jetty.http.UriCanonicalizer.CharacterClass;
+import org.eclipse.jetty.http.UriCanonicalizer.Decoding;
+import org.eclipse.jetty.http.UriCanonicalizer.Violation;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.Arguments;
+import org.junit.jupiter.params.provider.MethodSource;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.empty;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class UriCanonicalizerTest
+{
+    private final List<ComplianceViolation.Event> events = new CopyOnWriteArrayList<>();
+    private final ComplianceViolation.Listener listener = new ComplianceViolation.Listener()
+    {
+        @Override
+        public void onComplianceViolation(ComplianceViolation.Event event)
+        {
+            events.add(event);
+        }
+    };
+
+    public static Stream<Arguments> canonicalPaths()
+    {
+        return Stream.of(
+            Arguments.of("/", "/", "/"),
+            Arguments.of("/foo/bar", "/foo/bar", "/foo/bar"),
+            Arguments.of("/f%6f%6F/%7ebar", "/foo/~bar", "/foo/~bar"),
+            Arguments.of("/foo/./bar/../baz", "/foo/baz", "/foo/baz"),
+            Arguments.of("/foo/bar/..", "/foo/", "/foo/"),
+            Arguments.of("/foo/%2e%2e/bar", "/bar", "/bar"),
+            Arguments.of("/foo%2fbar", "/foo%2Fbar", "/foo/bar"),
+            Arguments.of("/foo%3bbar;p=1/baz", "/foo%3Bbar;p=1/baz", "/foo;bar/baz"),
+            Arguments.of("/a%20b", "/a%20b", "/a b"),
+            Arguments.of("/caf%c3%a9", "/caf%C3%A9", "/café"),
+            Arguments.of("/smile%f0%9f%98%80", "/smile%F0%9F%98%80", "/smile😀"),
+            Arguments.of("/100%25", "/100%25", "/100%")
+        );
+    }
+
+    @ParameterizedTest
+    @MethodSource("canonicalPaths")
+    public void testCanonicalPath(String path, String canonical, String decoded)
+    {
+        UriCanonicalizer.CanonicalPath result = new UriCanonicalizer().canonicalize(path);
+        assertThat(result.getPath(), is(canonical));
+        assertThat(result.getDecodedPath(), is(decoded));
+    }
+
+    public static Stream<Arguments> rejectedPaths()
+    {
+        return Stream.of(
+            Arguments.of("/foo/%c0%ae%c0%ae/secret", Violation.OVERLONG_UTF8),
+            Arguments.of("/foo/%e0%80%ae", Violation.OVERLONG_UTF8),
+            Arguments.of("/foo/%f0%80%80%ae", Violation.OVERLONG_UTF8),
+            Arguments.of("/foo/%ed%a0%80", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%f4%90%80%80", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%80", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%c3", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%c3x", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%c3%28", Violation.INVALID_UTF8),
+            Arguments.of("/foo/%00", Violation.ENCODED_CHARACTER),
+            Arguments.of("/foo/%7F", Violation.ENCODED_CHARACTER),
+            Arguments.of("/foo%5c..%5csecret", Violation.BACKSLASH),
+            Arguments.of("/foo\\bar", Violation.BACKSLASH),
+            Arguments.of("/foo/%252e%252e/secret", Violation.DOUBLE_ENCODING),
+            Arguments.of("/foo/%25%32%65", Violation.DOUBLE_ENCODING),
+            Arguments.of("/foo/..%2fsecret", Violation.DECODED_DOT_SEGMENT),
+            Arguments.of("/foo/..;/secret", Violation.DECODED_DOT_SEGMENT),
+            Arguments.of("/foo/.%2e;p/secret", Violation.DECODED_DOT_SEGMENT)
+        );
+    }
+
+    @ParameterizedTest
+    @MethodSource("rejectedPaths")
+    public void testRejectedPath(String path, Violation violation)
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        assertThrows(BadMessageException.class, () -> canonicalizer.canonicalize(path, listener));
+
+        ComplianceViolation.Event event = events.get(events.size() - 1);
+        assertThat(event.getViolation(), is(violation));
+        assertThat(event.isAllowed(), is(false));
+        assertThat(event.getMode(), sameInstance(canonicalizer));
+        assertThat(event.getDetails(), is(path));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {"/foo%zz", "/foo%:0", "/foo%2", "/foo%u0041", "/../secret", "/foo/../../secret", "foo", ""})
+    public void testBadPath(String path)
+    {
+        assertThrows(BadMessageException.class, () -> new UriCanonicalizer().canonicalize(path, listener));
+        assertThat(events, empty());
+    }
+
+    @Test
+    public void testDecoding()
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        canonicalizer.setDecoding(CharacterClass.SEPARATOR, Decoding.DECODE);
+        canonicalizer.setDecoding(CharacterClass.RESERVED, Decoding.REJECT);
+        canonicalizer.setDecoding(CharacterClass.NON_ASCII, Decoding.DECODE);
+
+        // Decoded separators are dot segment boundaries.
+        assertThat(canonicalizer.canonicalize("/foo%2f..%2fbar").getPath(), is("/bar"));
+        assertThat(canonicalizer.canonicalize("/caf%C3%A9").getPath(), is("/café"));
+        assertThrows(BadMessageException.class, () -> canonicalizer.canonicalize("/foo%3Bbar"));
+        assertThrows(IllegalArgumentException.class, () -> canonicalizer.setDecoding(CharacterClass.PERCENT, Decoding.DECODE));
+    }
+
+    @Test
+    public void testBackslash()
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        canonicalizer.setBackslash(Backslash.SEPARATOR);
+        UriCanonicalizer.CanonicalPath canonical = canonicalizer.canonicalize("/foo%5c..\\bar", listener);
+        assertThat(canonical.getPath(), is("/bar"));
+        assertThat(events.get(0).getViolation(), is(Violation.BACKSLASH));
+        assertThat(events.get(0).isAllowed(), is(true));
+
+        canonicalizer.setBackslash(Backslash.PRESERVE);
+        canonical = canonicalizer.canonicalize("/foo\\bar");
+        assertThat(canonical.getPath(), is("/foo%5Cbar"));
+        assertThat(canonical.getDecodedPath(), is("/foo\\bar"));
+    }
+
+    @Test
+    public void testDotSegments()
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        canonicalizer.addListener(listener);
+        assertThat(canonicalizer.canonicalize("/foo/./bar").getPath(), is("/foo/bar"));
+        assertThat(events.get(0).getViolation(), is(Violation.DOT_SEGMENT));
+        assertThat(events.get(0).isAllowed(), is(true));
+
+        canonicalizer.setAllowDotSegments(false);
+        assertThrows(BadMessageException.class, () -> canonicalizer.canonicalize("/foo/./bar"));
+        assertThat(events.get(1).isAllowed(), is(false));
+        assertThat(canonicalizer.getAllowed().contains(Violation.DOT_SEGMENT), is(false));
+    }
+
+    @Test
+    public void testAllowDoubleEncoding()
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        canonicalizer.setAllowDoubleEncoding(true);
+        UriCanonicalizer.CanonicalPath canonical = canonicalizer.canonicalize("/foo/%252e", listener);
+        assertThat(canonical.getPath(), is("/foo/%252e"));
+        assertThat(canonical.getDecodedPath(), is("/foo/%2e"));
+        assertThat(events.get(0).getViolation(), is(Violation.DOUBLE_ENCODING));
+        assertThat(events.get(0).isAllowed(), is(true));
+    }
+}
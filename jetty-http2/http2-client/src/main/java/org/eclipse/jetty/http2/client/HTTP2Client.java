@@ -116,6 +116,7 @@ public class HTTP2Client extends ContainerLifeCycle
     private int maxResponseHeadersSize = -1;
     private FlowControlStrategy.Factory flowControlStrategyFactory = () -> new BufferingFlowControlStrategy(0.5F);
     private long streamIdleTimeout;
+    private long maxStreamStallTime;
     private boolean useInputDirectByteBuffers = true;
     private boolean useOutputDirectByteBuffers = true;
     private boolean isUseALPN = true;
@@ -209,6 +210,22 @@ public class HTTP2Client extends ContainerLifeCycle
         this.streamIdleTimeout = streamIdleTimeout;
     }
 
+    @ManagedAttribute("The max time in milliseconds a stream may be stalled by flow control")
+    public long getMaxStreamStallTime()
+    {
+        return maxStreamStallTime;
+    }
+
+    /**
+     * @param maxStreamStallTime the max time in milliseconds a stream may be stalled
+     * by flow control before it is reset, or a value less than or equal to zero to disable
+     * @see org.eclipse.jetty.http2.HTTP2Session#setMaxStreamStallTime(long)
+     */
+    public void setMaxStreamStallTime(long maxStreamStallTime)
+    {
+        this.maxStreamStallTime = maxStreamStallTime;
+    }
+
     @ManagedAttribute("The connect timeout in milliseconds")
     public long getConnectTimeout()
     {
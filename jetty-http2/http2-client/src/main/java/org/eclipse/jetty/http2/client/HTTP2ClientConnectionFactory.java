@@ -71,6 +71,7 @@ public class HTTP2ClientConnectionFactory implements ClientConnectionFactory
         long streamIdleTimeout = client.getStreamIdleTimeout();
         if (streamIdleTimeout > 0)
             session.setStreamIdleTimeout(streamIdleTimeout);
+        session.setMaxStreamStallTime(client.getMaxStreamStallTime());
 
         RetainableByteBufferPool retainableByteBufferPool = byteBufferPool.asRetainableByteBufferPool();
         HTTP2ClientConnection connection = new HTTP2ClientConnection(client, retainableByteBufferPool, executor, endPoint,
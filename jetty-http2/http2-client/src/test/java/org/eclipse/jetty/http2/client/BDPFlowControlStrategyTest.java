@@ -0,0 +1,153 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.client;
+
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http2.BDPFlowControlStrategy;
+import org.eclipse.jetty.http2.FlowControlStrategy;
+import org.eclipse.jetty.http2.api.Session;
+import org.eclipse.jetty.http2.api.Stream;
+import org.eclipse.jetty.http2.api.server.ServerSessionListener;
+import org.eclipse.jetty.http2.frames.DataFrame;
+import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.FuturePromise;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class BDPFlowControlStrategyTest extends AbstractTest
+{
+    private final AtomicReference<BDPFlowControlStrategy> serverStrategy = new AtomicReference<>();
+
+    private void startServer(ServerSessionListener listener) throws Exception
+    {
+        start(listener, factory -> factory.setFlowControlStrategyFactory(() ->
+        {
+            BDPFlowControlStrategy strategy = new BDPFlowControlStrategy();
+            serverStrategy.set(strategy);
+            return strategy;
+        }));
+    }
+
+    @Test
+    public void testUploadMeasuresRoundTrips() throws Exception
+    {
+        int length = 4 * 1024 * 1024;
+        AtomicLong received = new AtomicLong();
+        AtomicBoolean serverPing = new AtomicBoolean();
+        CountDownLatch latch = new CountDownLatch(1);
+        startServer(new ServerSessionListener.Adapter()
+        {
+            @Override
+            public void onPing(Session session, PingFrame frame)
+            {
+                serverPing.set(true);
+            }
+
+            @Override
+            public Stream.Listener onNewStream(Stream stream, HeadersFrame frame)
+            {
+                return new Stream.Listener.Adapter()
+                {
+                    @Override
+                    public void onData(Stream stream, DataFrame frame, Callback callback)
+                    {
+                        received.addAndGet(frame.remaining());
+                        callback.succeeded();
+                        if (frame.isEndStream())
+                            latch.countDown();
+                    }
+                };
+            }
+        });
+
+        Session session = newClient(new Session.Listener.Adapter());
+        MetaData.Request request = newRequest("POST", HttpFields.EMPTY);
+        FuturePromise<Stream> promise = new FuturePromise<>();
+        session.newStream(new HeadersFrame(request, null, false), promise, new Stream.Listener.Adapter());
+        Stream stream = promise.get(5, TimeUnit.SECONDS);
+        stream.data(new DataFrame(stream.getId(), ByteBuffer.allocate(length), true), Callback.NOOP);
+
+        assertTrue(latch.await(15, TimeUnit.SECONDS));
+        assertEquals(length, received.get());
+
+        BDPFlowControlStrategy strategy = serverStrategy.get();
+        await().atMost(5, TimeUnit.SECONDS).until(() -> strategy.getRoundTrips() > 0);
+        // The BDP PING replies are not notified to the application.
+        assertFalse(serverPing.get());
+        assertTrue(strategy.getSessionRecvWindow() >= FlowControlStrategy.DEFAULT_WINDOW_SIZE);
+        assertTrue(strategy.getSessionRecvWindow() <= strategy.getMaxSessionRecvWindow());
+        assertTrue(strategy.getInitialStreamRecvWindow() >= FlowControlStrategy.DEFAULT_WINDOW_SIZE);
+        assertTrue(strategy.getInitialStreamRecvWindow() <= strategy.getMaxStreamRecvWindow());
+    }
+
+    @Test
+    public void testWindowsDoNotGrowWhenApplicationDoesNotConsume() throws Exception
+    {
+        List<Callback> callbacks = new ArrayList<>();
+        startServer(new ServerSessionListener.Adapter()
+        {
+            @Override
+            public Stream.Listener onNewStream(Stream stream, HeadersFrame frame)
+            {
+                return new Stream.Listener.Adapter()
+                {
+                    @Override
+                    public void onData(Stream stream, DataFrame frame, Callback callback)
+                    {
+                        // Do not consume the data.
+                        synchronized (callbacks)
+                        {
+                            callbacks.add(callback);
+                        }
+                    }
+                };
+            }
+        });
+
+        Session session = newClient(new Session.Listener.Adapter());
+        MetaData.Request request = newRequest("POST", HttpFields.EMPTY);
+        FuturePromise<Stream> promise = new FuturePromise<>();
+        session.newStream(new HeadersFrame(request, null, false), promise, new Stream.Listener.Adapter());
+        Stream stream = promise.get(5, TimeUnit.SECONDS);
+        stream.data(new DataFrame(stream.getId(), ByteBuffer.allocate(2 * FlowControlStrategy.DEFAULT_WINDOW_SIZE), true), Callback.NOOP);
+
+        BDPFlowControlStrategy strategy = serverStrategy.get();
+        await().atMost(5, TimeUnit.SECONDS).until(() -> strategy.getRoundTrips() > 0);
+
+        assertEquals(0, strategy.getWindowGrowths());
+        assertEquals(FlowControlStrategy.DEFAULT_WINDOW_SIZE, strategy.getSessionRecvWindow());
+        assertEquals(FlowControlStrategy.DEFAULT_WINDOW_SIZE, strategy.getInitialStreamRecvWindow());
+
+        synchronized (callbacks)
+        {
+            callbacks.forEach(Callback::succeeded);
+        }
+    }
+}
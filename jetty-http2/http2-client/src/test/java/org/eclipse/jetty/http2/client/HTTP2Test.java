@@ -47,8 +47,10 @@ import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
 import org.eclipse.jetty.http2.hpack.HpackException;
+import org.eclipse.jetty.http2.server.HTTP2CServerConnectionFactory;
 import org.eclipse.jetty.http2.server.RawHTTP2ServerConnectionFactory;
 import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.RuleBasedHeaderPolicy;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.FuturePromise;
@@ -101,6 +103,52 @@ public class HTTP2Test extends AbstractTest
         assertTrue(latch.await(5, TimeUnit.SECONDS));
     }
 
+    @Test
+    public void testHeaderPolicy() throws Exception
+    {
+        start(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response)
+            {
+                String user = request.getHeader("X-Internal-User");
+                response.setHeader("X-Seen", user == null ? "none" : user);
+            }
+        });
+        RuleBasedHeaderPolicy policy = new RuleBasedHeaderPolicy();
+        RuleBasedHeaderPolicy.Rule drop = policy.drop("X-Internal-*");
+        RuleBasedHeaderPolicy.Rule reject = policy.reject("X-Reject", null);
+        connector.getConnectionFactory(HTTP2CServerConnectionFactory.class).getHttpConfiguration().setHeaderPolicy(policy);
+
+        Session session = newClient(new Session.Listener.Adapter());
+
+        HttpFields fields = HttpFields.build().put("X-Internal-User", "admin");
+        MetaData.Response response = sendWithHeaders(session, fields);
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("none", response.getFields().get("X-Seen"));
+        assertEquals(1, drop.getCount());
+
+        response = sendWithHeaders(session, HttpFields.build().put("X-Reject", "true"));
+        assertEquals(HttpStatus.BAD_REQUEST_400, response.getStatus());
+        assertEquals(1, reject.getCount());
+    }
+
+    private MetaData.Response sendWithHeaders(Session session, HttpFields fields) throws Exception
+    {
+        MetaData.Request metaData = newRequest("GET", fields);
+        HeadersFrame frame = new HeadersFrame(metaData, null, true);
+        CompletableFuture<MetaData.Response> responseFuture = new CompletableFuture<>();
+        session.newStream(frame, new Promise.Adapter<>(), new Stream.Listener.Adapter()
+        {
+            @Override
+            public void onHeaders(Stream stream, HeadersFrame frame)
+            {
+                responseFuture.complete((MetaData.Response)frame.getMetaData());
+            }
+        });
+        return responseFuture.get(5, TimeUnit.SECONDS);
+    }
+
     @Test
     public void testRequestNoContentResponseEmptyContent() throws Exception
     {
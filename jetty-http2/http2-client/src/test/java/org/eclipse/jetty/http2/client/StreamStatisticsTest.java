@@ -0,0 +1,150 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.client;
+
+import java.nio.ByteBuffer;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http2.ErrorCode;
+import org.eclipse.jetty.http2.FlowControlStrategy;
+import org.eclipse.jetty.http2.api.Session;
+import org.eclipse.jetty.http2.api.Stream;
+import org.eclipse.jetty.http2.api.server.ServerSessionListener;
+import org.eclipse.jetty.http2.frames.DataFrame;
+import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.http2.frames.ResetFrame;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.FuturePromise;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class StreamStatisticsTest extends AbstractTest
+{
+    @Test
+    public void testStreamStatistics() throws Exception
+    {
+        int length = 1024;
+        AtomicReference<Stream> serverStreamRef = new AtomicReference<>();
+        CountDownLatch serverLatch = new CountDownLatch(1);
+        start(new ServerSessionListener.Adapter()
+        {
+            @Override
+            public Stream.Listener onNewStream(Stream stream, HeadersFrame frame)
+            {
+                serverStreamRef.set(stream);
+                MetaData.Response response = new MetaData.Response(HttpVersion.HTTP_2, HttpStatus.OK_200, HttpFields.EMPTY);
+                stream.headers(new HeadersFrame(stream.getId(), response, null, false), Callback.from(() ->
+                    stream.data(new DataFrame(stream.getId(), ByteBuffer.allocate(length), true), Callback.from(serverLatch::countDown))));
+                return null;
+            }
+        });
+
+        Session session = newClient(new Session.Listener.Adapter());
+        CountDownLatch clientLatch = new CountDownLatch(1);
+        FuturePromise<Stream> promise = new FuturePromise<>();
+        session.newStream(new HeadersFrame(newRequest("GET", HttpFields.EMPTY), null, true), promise, new Stream.Listener.Adapter()
+        {
+            @Override
+            public void onData(Stream stream, DataFrame frame, Callback callback)
+            {
+                callback.succeeded();
+                if (frame.isEndStream())
+                    clientLatch.countDown();
+            }
+        });
+        Stream clientStream = promise.get(5, TimeUnit.SECONDS);
+
+        assertTrue(serverLatch.await(5, TimeUnit.SECONDS));
+        assertTrue(clientLatch.await(5, TimeUnit.SECONDS));
+
+        Stream.Statistics serverStatistics = serverStreamRef.get().getStatistics();
+        assertEquals(1, serverStatistics.getFramesReceived());
+        assertEquals(0, serverStatistics.getBytesReceived());
+        assertEquals(2, serverStatistics.getFramesSent());
+        assertEquals(length, serverStatistics.getBytesSent());
+        assertEquals(0, serverStatistics.getFlowControlStalls());
+
+        Stream.Statistics clientStatistics = clientStream.getStatistics();
+        assertEquals(1, clientStatistics.getFramesSent());
+        assertEquals(2, clientStatistics.getFramesReceived());
+        assertEquals(length, clientStatistics.getBytesReceived());
+    }
+
+    @Test
+    public void testStalledStreamIsReset() throws Exception
+    {
+        long maxStreamStallTime = 500;
+        AtomicReference<Stream> serverStreamRef = new AtomicReference<>();
+        CountDownLatch serverLatch = new CountDownLatch(1);
+        start(new ServerSessionListener.Adapter()
+        {
+            @Override
+            public Stream.Listener onNewStream(Stream stream, HeadersFrame frame)
+            {
+                serverStreamRef.set(stream);
+                MetaData.Response response = new MetaData.Response(HttpVersion.HTTP_2, HttpStatus.OK_200, HttpFields.EMPTY);
+                stream.headers(new HeadersFrame(stream.getId(), response, null, false), Callback.from(() ->
+                {
+                    // Send more data than the flow control window allows.
+                    ByteBuffer data = ByteBuffer.allocate(FlowControlStrategy.DEFAULT_WINDOW_SIZE + 1);
+                    stream.data(new DataFrame(stream.getId(), data, true), Callback.from(() -> {}, x -> serverLatch.countDown()));
+                }));
+                return null;
+            }
+        }, connectionFactory -> connectionFactory.setMaxStreamStallTime(maxStreamStallTime));
+
+        // Use a large session window so that only the stream gets stalled.
+        client.setInitialSessionRecvWindow(5 * FlowControlStrategy.DEFAULT_WINDOW_SIZE);
+        Session session = newClient(new Session.Listener.Adapter());
+        CountDownLatch resetLatch = new CountDownLatch(1);
+        AtomicReference<ResetFrame> resetRef = new AtomicReference<>();
+        session.newStream(new HeadersFrame(newRequest("GET", HttpFields.EMPTY), null, true), new FuturePromise<>(), new Stream.Listener.Adapter()
+        {
+            @Override
+            public void onData(Stream stream, DataFrame frame, Callback callback)
+            {
+                // Never complete the callback, so the
+                // flow control window is never enlarged.
+            }
+
+            @Override
+            public void onReset(Stream stream, ResetFrame frame)
+            {
+                resetRef.set(frame);
+                resetLatch.countDown();
+            }
+        });
+
+        assertTrue(resetLatch.await(5, TimeUnit.SECONDS));
+        assertThat(resetRef.get().getError(), is(ErrorCode.ENHANCE_YOUR_CALM_ERROR.code));
+        assertTrue(serverLatch.await(5, TimeUnit.SECONDS));
+
+        Stream.Statistics serverStatistics = serverStreamRef.get().getStatistics();
+        assertEquals(1, serverStatistics.getFlowControlStalls());
+        assertThat(serverStatistics.getFlowControlStallTime(), greaterThanOrEqualTo(maxStreamStallTime));
+        assertThat(serverStatistics.getBytesSent(), greaterThan(0L));
+    }
+}
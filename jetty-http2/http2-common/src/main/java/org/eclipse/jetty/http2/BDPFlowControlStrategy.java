@@ -0,0 +1,304 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2;
+
+import java.util.Map;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.http2.frames.SettingsFrame;
+import org.eclipse.jetty.http2.frames.WindowUpdateFrame;
+import org.eclipse.jetty.util.Atomics;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A flow control strategy that sizes the receive windows based on an
+ * estimation of the bandwidth-delay product (BDP) of the connection.</p>
+ * <p>When data is received, a PING frame is sent to the peer, and the bytes
+ * received until the PING reply arrives are counted: this sample approximates
+ * the number of bytes that the peer can send in one round trip.
+ * If the sample is close to the current receive window, and the measured
+ * bandwidth grew, the window is limiting the throughput: the session window
+ * and the initial stream window are then enlarged to twice the sample, up to
+ * {@link #getMaxSessionRecvWindow()} and {@link #getMaxStreamRecvWindow()},
+ * by sending respectively a WINDOW_UPDATE frame and a SETTINGS frame.</p>
+ * <p>Windows are only enlarged when the application keeps up with the data;
+ * when the application does not consume the data, enlarging the windows would
+ * only buffer more data.
+ * Windows are never shrunk.</p>
+ * <p>Window updates are emitted as in {@link BufferingFlowControlStrategy}.</p>
+ */
+@ManagedObject
+public class BDPFlowControlStrategy extends BufferingFlowControlStrategy
+{
+    private final AutoLock lock = new AutoLock();
+    private final AtomicInteger sessionRecvWindow = new AtomicInteger(DEFAULT_WINDOW_SIZE);
+    private final AtomicLong unconsumed = new AtomicLong();
+    private final LongAdder roundTrips = new LongAdder();
+    private final LongAdder windowGrowths = new LongAdder();
+    private final long pingPayload = ThreadLocalRandom.current().nextLong();
+    private int maxSessionRecvWindow = 16 * 1024 * 1024;
+    private int maxStreamRecvWindow = 8 * 1024 * 1024;
+    private long pingNanoTime;
+    private long sample;
+    private volatile long roundTripTime;
+    private volatile double bandwidth;
+    private volatile long bdp;
+
+    public BDPFlowControlStrategy()
+    {
+        this(0.5F);
+    }
+
+    public BDPFlowControlStrategy(float bufferRatio)
+    {
+        this(DEFAULT_WINDOW_SIZE, bufferRatio);
+    }
+
+    public BDPFlowControlStrategy(int initialStreamSendWindow, float bufferRatio)
+    {
+        super(initialStreamSendWindow, bufferRatio);
+    }
+
+    @ManagedAttribute("The max size of the session receive window")
+    public int getMaxSessionRecvWindow()
+    {
+        return maxSessionRecvWindow;
+    }
+
+    public void setMaxSessionRecvWindow(int maxSessionRecvWindow)
+    {
+        if (maxSessionRecvWindow < DEFAULT_WINDOW_SIZE)
+            throw new IllegalArgumentException("Invalid max session recv window " + maxSessionRecvWindow);
+        this.maxSessionRecvWindow = maxSessionRecvWindow;
+    }
+
+    @ManagedAttribute("The max size of the initial stream receive window")
+    public int getMaxStreamRecvWindow()
+    {
+        return maxStreamRecvWindow;
+    }
+
+    public void setMaxStreamRecvWindow(int maxStreamRecvWindow)
+    {
+        if (maxStreamRecvWindow < DEFAULT_WINDOW_SIZE)
+            throw new IllegalArgumentException("Invalid max stream recv window " + maxStreamRecvWindow);
+        this.maxStreamRecvWindow = maxStreamRecvWindow;
+    }
+
+    @ManagedAttribute(value = "The current size of the session receive window", readonly = true)
+    public int getSessionRecvWindow()
+    {
+        return sessionRecvWindow.get();
+    }
+
+    @ManagedAttribute(value = "The estimated bandwidth-delay product, in bytes", readonly = true)
+    public long getEstimatedBDP()
+    {
+        return bdp;
+    }
+
+    @ManagedAttribute(value = "The estimated bandwidth, in bytes per second", readonly = true)
+    public long getEstimatedBandwidth()
+    {
+        return (long)bandwidth;
+    }
+
+    @ManagedAttribute(value = "The smoothed round trip time, in microseconds", readonly = true)
+    public long getRoundTripTime()
+    {
+        return TimeUnit.NANOSECONDS.toMicros(roundTripTime);
+    }
+
+    @ManagedAttribute(value = "The number of round trips measured", readonly = true)
+    public long getRoundTrips()
+    {
+        return roundTrips.sum();
+    }
+
+    @ManagedAttribute(value = "The number of times the receive windows have been enlarged", readonly = true)
+    public long getWindowGrowths()
+    {
+        return windowGrowths.sum();
+    }
+
+    @Override
+    public void onDataReceived(ISession session, IStream stream, int length)
+    {
+        super.onDataReceived(session, stream, length);
+        if (length <= 0)
+            return;
+
+        unconsumed.addAndGet(length);
+
+        boolean ping = false;
+        try (AutoLock l = lock.lock())
+        {
+            if (pingNanoTime == 0)
+            {
+                pingNanoTime = NanoTime.now();
+                sample = 0;
+                ping = true;
+            }
+            sample += length;
+        }
+
+        if (ping)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Sending BDP ping for {}", session);
+            session.ping(new PingFrame(pingPayload, false), Callback.NOOP);
+        }
+    }
+
+    @Override
+    public void onDataConsumed(ISession session, IStream stream, int length)
+    {
+        if (length > 0)
+            unconsumed.addAndGet(-length);
+        super.onDataConsumed(session, stream, length);
+    }
+
+    @Override
+    public boolean onPingReply(ISession session, PingFrame frame)
+    {
+        if (frame.getPayloadAsLong() != pingPayload)
+            return false;
+
+        long rtt;
+        long bytes;
+        try (AutoLock l = lock.lock())
+        {
+            if (pingNanoTime == 0)
+                return true;
+            rtt = Math.max(1, NanoTime.since(pingNanoTime));
+            bytes = sample;
+            pingNanoTime = 0;
+            sample = 0;
+        }
+
+        roundTrips.increment();
+        long srtt = roundTripTime;
+        srtt = srtt == 0 ? rtt : (7 * srtt + rtt) / 8;
+        roundTripTime = srtt;
+
+        onRoundTrip(session, bytes, rtt);
+        return true;
+    }
+
+    /**
+     * <p>Invoked when a round trip has been measured, to update the BDP estimation
+     * and possibly enlarge the receive windows.</p>
+     *
+     * @param session the session
+     * @param bytes the bytes received during the round trip
+     * @param rtt the round trip time, in nanoseconds
+     */
+    protected void onRoundTrip(ISession session, long bytes, long rtt)
+    {
+        int sessionWindow = sessionRecvWindow.get();
+        int streamWindow = getInitialStreamRecvWindow();
+        boolean sessionLimited = sessionWindow < getMaxSessionRecvWindow();
+        boolean streamLimited = streamWindow < getMaxStreamRecvWindow();
+        if (!sessionLimited && !streamLimited)
+            return;
+
+        // The sample must be close to the limiting window,
+        // otherwise the window is not limiting the throughput.
+        int window = Math.min(sessionWindow, streamWindow);
+        if (bytes < window * 2L / 3)
+            return;
+
+        // If the application does not keep up with
+        // the data, larger windows would only buffer more.
+        if (unconsumed.get() > window / 2)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("BDP sample {} bytes ignored, application not consuming for {}", bytes, session);
+            return;
+        }
+
+        // Use a conservative round trip time to absorb jitter.
+        double sampleBandwidth = bytes * (double)TimeUnit.SECONDS.toNanos(1) / Math.max(rtt, roundTripTime);
+        if (sampleBandwidth <= bandwidth)
+            return;
+        bandwidth = sampleBandwidth;
+        bdp = bytes;
+
+        long target = 2 * bytes;
+        boolean grown = false;
+
+        int newSessionWindow = (int)Math.min(target, getMaxSessionRecvWindow());
+        int sessionDelta = newSessionWindow - sessionWindow;
+        if (sessionDelta > 0 && Atomics.updateMax(sessionRecvWindow, newSessionWindow))
+        {
+            session.updateRecvWindow(sessionDelta);
+            if (LOG.isDebugEnabled())
+                LOG.debug("BDP {} bytes, enlarged session recv window {} -> {} for {}", bytes, sessionWindow, newSessionWindow, session);
+            sendWindowUpdate(null, session, new WindowUpdateFrame(0, sessionDelta));
+            grown = true;
+        }
+
+        int newStreamWindow = (int)Math.min(target, getMaxStreamRecvWindow());
+        if (newStreamWindow > streamWindow)
+        {
+            // Update the local windows before sending the SETTINGS frame,
+            // so that data sent by the peer after the reception of the
+            // SETTINGS frame cannot exceed the local stream windows.
+            updateInitialStreamWindow(session, newStreamWindow, true);
+            if (LOG.isDebugEnabled())
+                LOG.debug("BDP {} bytes, enlarged initial stream recv window {} -> {} for {}", bytes, streamWindow, newStreamWindow, session);
+            session.settings(new SettingsFrame(Map.of(SettingsFrame.INITIAL_WINDOW_SIZE, newStreamWindow), false), Callback.NOOP);
+            grown = true;
+        }
+
+        if (grown)
+            windowGrowths.increment();
+    }
+
+    @Override
+    public void windowUpdate(ISession session, IStream stream, WindowUpdateFrame frame)
+    {
+        super.windowUpdate(session, stream, frame);
+        // Track session window enlargements not performed by this
+        // class, such as the initial session window configuration.
+        if (frame.getStreamId() == 0)
+            Atomics.updateMax(sessionRecvWindow, session.updateRecvWindow(0));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[ratio=%.2f,sessionRecvWindow=%d/%d,streamRecvWindow=%d/%d,bdp=%d,rtt=%dus,sessionStallTime=%dms,streamsStallTime=%dms]",
+            getClass().getSimpleName(),
+            hashCode(),
+            getBufferRatio(),
+            getSessionRecvWindow(),
+            getMaxSessionRecvWindow(),
+            getInitialStreamRecvWindow(),
+            getMaxStreamRecvWindow(),
+            getEstimatedBDP(),
+            getRoundTripTime(),
+            getSessionStallTime(),
+            getStreamsStallTime());
+    }
+}
@@ -0,0 +1,167 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2;
+
+import java.util.List;
+
+import org.eclipse.jetty.http.HttpFields;
+
+/**
+ * <p>The priority of a stream as defined by RFC 9218, made of an urgency
+ * from 0 (most urgent) to 7 (least urgent), and an incremental flag that
+ * indicates whether the response can be processed incrementally, and
+ * therefore can share the bandwidth with other responses of the same urgency.</p>
+ * <p>The priority is carried by the {@code Priority} HTTP header, that is a
+ * Structured Fields dictionary such as {@code u=1, i}, or by
+ * {@link org.eclipse.jetty.http2.frames.PriorityUpdateFrame PRIORITY_UPDATE} frames.</p>
+ */
+public class ExtensiblePriority
+{
+    public static final String PRIORITY_HEADER = "Priority";
+    public static final int MIN_URGENCY = 0;
+    public static final int DEFAULT_URGENCY = 3;
+    public static final int MAX_URGENCY = 7;
+    public static final ExtensiblePriority DEFAULT = new ExtensiblePriority(DEFAULT_URGENCY, false);
+
+    private final int urgency;
+    private final boolean incremental;
+
+    public ExtensiblePriority(int urgency, boolean incremental)
+    {
+        if (urgency < MIN_URGENCY || urgency > MAX_URGENCY)
+            throw new IllegalArgumentException("Invalid urgency " + urgency);
+        this.urgency = urgency;
+        this.incremental = incremental;
+    }
+
+    public int getUrgency()
+    {
+        return urgency;
+    }
+
+    public boolean isIncremental()
+    {
+        return incremental;
+    }
+
+    /**
+     * @return this priority in the format of the {@code Priority} HTTP header value
+     */
+    public String toHeaderValue()
+    {
+        return incremental ? "u=" + urgency + ", i" : "u=" + urgency;
+    }
+
+    /**
+     * <p>Returns the priority carried by the {@code Priority} header of the given fields.</p>
+     *
+     * @param fields the HTTP fields, may be null
+     * @return the priority, or {@link #DEFAULT} if the fields do not carry a priority
+     */
+    public static ExtensiblePriority from(HttpFields fields)
+    {
+        if (fields == null)
+            return DEFAULT;
+        List<String> values = fields.getValuesList(PRIORITY_HEADER);
+        if (values.isEmpty())
+            return DEFAULT;
+        return parse(String.join(",", values));
+    }
+
+    /**
+     * <p>Parses the given {@code Priority} header value.</p>
+     * <p>As specified by RFC 9218, unknown parameters and invalid
+     * values are ignored, and omitted parameters take their default value.</p>
+     *
+     * @param value the {@code Priority} header value
+     * @return the priority
+     */
+    public static ExtensiblePriority parse(String value)
+    {
+        if (value == null)
+            return DEFAULT;
+
+        int urgency = DEFAULT_URGENCY;
+        boolean incremental = false;
+        for (String member : value.split(","))
+        {
+            // Ignore the parameters of the dictionary members.
+            int semicolon = member.indexOf(';');
+            if (semicolon >= 0)
+                member = member.substring(0, semicolon);
+            member = member.trim();
+            if (member.isEmpty())
+                continue;
+
+            int equal = member.indexOf('=');
+            String key = equal < 0 ? member : member.substring(0, equal).trim();
+            String item = equal < 0 ? "?1" : member.substring(equal + 1).trim();
+            switch (key)
+            {
+                case "u":
+                {
+                    try
+                    {
+                        int u = Integer.parseInt(item);
+                        if (u >= MIN_URGENCY && u <= MAX_URGENCY)
+                            urgency = u;
+                    }
+                    catch (NumberFormatException ignored)
+                    {
+                        // Invalid values are ignored.
+                    }
+                    break;
+                }
+                case "i":
+                {
+                    if ("?1".equals(item))
+                        incremental = true;
+                    else if ("?0".equals(item))
+                        incremental = false;
+                    break;
+                }
+                default:
+                {
+                    break;
+                }
+            }
+        }
+        if (urgency == DEFAULT_URGENCY && !incremental)
+            return DEFAULT;
+        return new ExtensiblePriority(urgency, incremental);
+    }
+
+    @Override
+    public boolean equals(Object obj)
+    {
+        if (this == obj)
+            return true;
+        if (!(obj instanceof ExtensiblePriority))
+            return false;
+        ExtensiblePriority that = (ExtensiblePriority)obj;
+        return urgency == that.urgency && incremental == that.incremental;
+    }
+
+    @Override
+    public int hashCode()
+    {
+        return urgency * 2 + (incremental ? 1 : 0);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s}", getClass().getSimpleName(), hashCode(), toHeaderValue());
+    }
+}
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http2;
 
+import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.WindowUpdateFrame;
 
 public interface FlowControlStrategy
@@ -37,6 +38,21 @@ public interface FlowControlStrategy
 
     public void onDataSent(IStream stream, int length);
 
+    /**
+     * <p>Invoked when a PING reply is received.</p>
+     * <p>Strategies that send PING frames, for example to measure the
+     * round trip time, may intercept the replies to their own PING frames.</p>
+     *
+     * @param session the session
+     * @param frame the PING reply frame
+     * @return whether the PING reply has been consumed by this strategy,
+     * in which case it is not notified to the application
+     */
+    public default boolean onPingReply(ISession session, PingFrame frame)
+    {
+        return false;
+    }
+
     public interface Factory
     {
         public FlowControlStrategy newFlowControlStrategy();
@@ -25,6 +25,7 @@ import org.eclipse.jetty.http2.frames.GoAwayFrame;
 import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.PriorityFrame;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
 import org.eclipse.jetty.http2.frames.PushPromiseFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
@@ -254,6 +255,12 @@ public class HTTP2Connection extends AbstractConnection implements Parser.Listen
         session.onPriority(frame);
     }
 
+    @Override
+    public void onPriorityUpdate(PriorityUpdateFrame frame)
+    {
+        session.onPriorityUpdate(frame);
+    }
+
     @Override
     public void onReset(ResetFrame frame)
     {
@@ -24,6 +24,7 @@ import java.util.Iterator;
 import java.util.List;
 import java.util.Queue;
 import java.util.Set;
+import java.util.concurrent.atomic.AtomicLongArray;
 
 import org.eclipse.jetty.http2.frames.Frame;
 import org.eclipse.jetty.http2.frames.FrameType;
@@ -33,6 +34,7 @@ import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.io.EofException;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.component.Dumpable;
 import org.eclipse.jetty.util.thread.AutoLock;
 import org.eclipse.jetty.util.thread.Invocable;
@@ -46,9 +48,12 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
 
     private final AutoLock lock = new AutoLock();
     private final Queue<WindowEntry> windows = new ArrayDeque<>();
+    private final Queue<PriorityEntry> priorities = new ArrayDeque<>();
     private final Deque<Entry> entries = new ArrayDeque<>();
     private final Queue<Entry> pendingEntries = new ArrayDeque<>();
     private final Collection<Entry> processedEntries = new ArrayList<>();
+    private final List<IStream> deferredStreams = new ArrayList<>();
+    private final AtomicLongArray dataBytesByUrgency = new AtomicLongArray(ExtensiblePriority.MAX_URGENCY + 1);
     private final HTTP2Session session;
     private final ByteBufferPool.Lease lease;
     private InvocationType invocationType = InvocationType.NON_BLOCKING;
@@ -81,6 +86,20 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
             iterate();
     }
 
+    public void priority(IStream stream, ExtensiblePriority priority)
+    {
+        Throwable closed;
+        try (AutoLock l = lock.lock())
+        {
+            closed = terminated;
+            if (closed == null)
+                priorities.offer(new PriorityEntry(stream, priority));
+        }
+        // Reschedule pending data.
+        if (closed == null)
+            iterate();
+    }
+
     public boolean prepend(Entry entry)
     {
         Throwable closed;
@@ -154,6 +173,19 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
         }
     }
 
+    /**
+     * @return the number of DATA bytes generated for each RFC 9218 urgency, indexed by urgency
+     */
+    public long[] getDataBytesByUrgency()
+    {
+        long[] result = new long[dataBytesByUrgency.length()];
+        for (int i = 0; i < result.length; ++i)
+        {
+            result[i] = dataBytesByUrgency.get(i);
+        }
+        return result;
+    }
+
     @Override
     protected Action process() throws Throwable
     {
@@ -171,6 +203,12 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
                 windowEntry.perform();
             }
 
+            PriorityEntry priorityEntry;
+            while ((priorityEntry = priorities.poll()) != null)
+            {
+                priorityEntry.perform();
+            }
+
             Entry entry;
             while ((entry = entries.poll()) != null)
             {
@@ -192,6 +230,10 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
             if (pendingEntries.isEmpty())
                 break;
 
+            StreamPrioritizer prioritizer = session.getStreamPrioritizer();
+            IStream prioritized = prioritizer == null ? null : prioritize(prioritizer);
+            deferredStreams.clear();
+
             Iterator<Entry> pending = pendingEntries.iterator();
             while (pending.hasNext())
             {
@@ -210,10 +252,36 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
                     continue;
                 }
 
+                // Defer the frames of streams less important than
+                // the prioritized one, but keep the frames of each
+                // stream in order, so once a stream is deferred all
+                // its subsequent frames are deferred in this pass.
+                if (prioritized != null && entry.stream != null)
+                {
+                    if (deferredStreams.contains(entry.stream))
+                        continue;
+                    if (entry.frame.getType() == FrameType.DATA && prioritizer.compare(prioritized, entry.stream) < 0)
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Deferred {} in favor of {}", entry, prioritized);
+                        deferredStreams.add(entry.stream);
+                        continue;
+                    }
+                }
+
                 try
                 {
+                    int dataBytesRemaining = entry.getDataBytesRemaining();
                     if (entry.generate(lease))
                     {
+                        entry.onGenerated();
+
+                        if (entry.stream != null && entry.frame.getType() == FrameType.DATA)
+                        {
+                            int urgency = entry.stream.getPriority().getUrgency();
+                            dataBytesByUrgency.addAndGet(urgency, dataBytesRemaining - entry.getDataBytesRemaining());
+                        }
+
                         if (LOG.isDebugEnabled())
                             LOG.debug("Generated {} frame bytes for {}", entry.getFrameBytesGenerated(), entry);
 
@@ -293,6 +361,28 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
         return Action.SCHEDULED;
     }
 
+    /**
+     * @param prioritizer the strategy to order streams
+     * @return the most important stream among those that have DATA frames that can be written, or null
+     */
+    private IStream prioritize(StreamPrioritizer prioritizer)
+    {
+        IStream result = null;
+        for (Entry entry : pendingEntries)
+        {
+            IStream stream = entry.stream;
+            if (stream == null || entry.frame.getType() != FrameType.DATA || stream.isResetOrFailed())
+                continue;
+            // Flow control stalled streams cannot make progress,
+            // so they must not prevent other streams from writing.
+            if (entry.getDataBytesRemaining() > 0 && stream.updateSendWindow(0) <= 0)
+                continue;
+            if (result == null || prioritizer.compare(stream, result) < 0)
+                result = stream;
+        }
+        return result;
+    }
+
     void onFlushed(long bytes) throws IOException
     {
         // A single EndPoint write may be flushed multiple times (for example with SSL).
@@ -370,6 +460,7 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
         processedEntries.clear();
         allEntries.addAll(pendingEntries);
         pendingEntries.clear();
+        deferredStreams.clear();
         allEntries.forEach(entry -> entry.failed(x));
 
         // If the failure came from within the
@@ -424,12 +515,20 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
     {
         protected final Frame frame;
         protected final IStream stream;
+        private long queueNanoTime;
+        private long queueStallNanos;
+        private boolean generated;
 
         protected Entry(Frame frame, IStream stream, Callback callback)
         {
             super(callback);
             this.frame = frame;
             this.stream = stream;
+            if (stream != null)
+            {
+                this.queueNanoTime = NanoTime.now();
+                this.queueStallNanos = stream.getStatistics().getFlowControlStallNanos();
+            }
         }
 
         public abstract int getFrameBytesGenerated();
@@ -448,6 +547,18 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
             return false;
         }
 
+        private void onGenerated()
+        {
+            if (stream == null || generated)
+                return;
+            generated = true;
+            // Only the first generation is accounted, excluding
+            // the time the frame was stalled by flow control.
+            HTTP2StreamStatistics statistics = stream.getStatistics();
+            long stallNanos = statistics.getFlowControlStallNanos() - queueStallNanos;
+            statistics.onHeadOfLineBlocked(NanoTime.since(queueNanoTime) - stallNanos);
+        }
+
         @Override
         public void failed(Throwable x)
         {
@@ -468,6 +579,7 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
             {
                 // Frames of this type should not be dropped.
                 case PRIORITY:
+                case PRIORITY_UPDATE:
                 case SETTINGS:
                 case PING:
                 case GO_AWAY:
@@ -527,4 +639,21 @@ public class HTTP2Flusher extends IteratingCallback implements Dumpable
             flowControl.onWindowUpdate(session, stream, frame);
         }
     }
+
+    private static class PriorityEntry
+    {
+        private final IStream stream;
+        private final ExtensiblePriority priority;
+
+        private PriorityEntry(IStream stream, ExtensiblePriority priority)
+        {
+            this.stream = stream;
+            this.priority = priority;
+        }
+
+        private void perform()
+        {
+            stream.setPriority(priority);
+        }
+    }
 }
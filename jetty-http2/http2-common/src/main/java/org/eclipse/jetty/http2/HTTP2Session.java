@@ -29,6 +29,7 @@ import java.util.Queue;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.AtomicLong;
@@ -48,12 +49,14 @@ import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.PrefaceFrame;
 import org.eclipse.jetty.http2.frames.PriorityFrame;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
 import org.eclipse.jetty.http2.frames.PushPromiseFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
 import org.eclipse.jetty.http2.frames.StreamFrame;
 import org.eclipse.jetty.http2.frames.WindowUpdateFrame;
 import org.eclipse.jetty.http2.generator.Generator;
+import org.eclipse.jetty.http2.hpack.HpackDecoder;
 import org.eclipse.jetty.http2.hpack.HpackEncoder;
 import org.eclipse.jetty.http2.hpack.HpackException;
 import org.eclipse.jetty.http2.parser.Parser;
@@ -101,11 +104,14 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
     private final FlowControlStrategy flowControl;
     private final HTTP2Flusher flusher;
     private final StreamTimeouts streamTimeouts;
+    private final StreamStallTimeouts streamStallTimeouts;
     private int maxLocalStreams;
     private int maxRemoteStreams;
     private long streamIdleTimeout;
+    private long maxStreamStallTime;
     private int initialSessionRecvWindow;
     private int writeThreshold;
+    private StreamPrioritizer streamPrioritizer;
     private int maxEncoderTableCapacity;
     private boolean pushEnabled;
     private boolean connectProtocolEnabled;
@@ -119,6 +125,7 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         this.flowControl = flowControl;
         this.flusher = new HTTP2Flusher(this);
         this.streamTimeouts = new StreamTimeouts(scheduler);
+        this.streamStallTimeouts = new StreamStallTimeouts(scheduler);
         this.maxLocalStreams = -1;
         this.maxRemoteStreams = -1;
         this.localStreamIds.set(initialStreamId);
@@ -126,6 +133,7 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         this.recvWindow.set(FlowControlStrategy.DEFAULT_WINDOW_SIZE);
         this.writeThreshold = 32 * 1024;
         this.pushEnabled = true; // SPEC: by default, push is enabled.
+        parser.getHpackDecoder().setListener(this::onCompressionRatioExceeded);
         addBean(flowControl);
         addBean(flusher);
     }
@@ -188,6 +196,31 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         this.streamIdleTimeout = streamIdleTimeout;
     }
 
+    /**
+     * @return the max time, in milliseconds, a stream may be stalled by flow control
+     * @see #setMaxStreamStallTime(long)
+     */
+    @ManagedAttribute("The max time, in milliseconds, a stream may be stalled by flow control")
+    public long getMaxStreamStallTime()
+    {
+        return maxStreamStallTime;
+    }
+
+    /**
+     * <p>Sets the max time, in milliseconds, a stream may be stalled by flow control,
+     * waiting for the peer to enlarge the flow control window, before being reset.</p>
+     * <p>The stall time is cumulative over the stream lifetime, so that peers that
+     * read slowly by sending small window updates are detected as well, protecting
+     * against slow-read attacks that keep the stream resources allocated.</p>
+     *
+     * @param maxStreamStallTime the max stall time in milliseconds, or a value
+     * less than or equal to zero to never reset stalled streams
+     */
+    public void setMaxStreamStallTime(long maxStreamStallTime)
+    {
+        this.maxStreamStallTime = maxStreamStallTime;
+    }
+
     @ManagedAttribute("The initial size of session's flow control receive window")
     public int getInitialSessionRecvWindow()
     {
@@ -210,6 +243,27 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         this.writeThreshold = writeThreshold;
     }
 
+    @ManagedAttribute("The strategy that orders the streams when writing DATA frames")
+    public StreamPrioritizer getStreamPrioritizer()
+    {
+        return streamPrioritizer;
+    }
+
+    /**
+     * @param streamPrioritizer the strategy that orders the streams when writing DATA frames,
+     * or {@code null} to write DATA frames in the order they are queued
+     */
+    public void setStreamPrioritizer(StreamPrioritizer streamPrioritizer)
+    {
+        this.streamPrioritizer = streamPrioritizer;
+    }
+
+    @ManagedAttribute(value = "The DATA bytes written for each RFC 9218 urgency", readonly = true)
+    public long[] getDataBytesByUrgency()
+    {
+        return flusher.getDataBytesByUrgency();
+    }
+
     @ManagedAttribute("The HPACK encoder dynamic table maximum capacity")
     public int getMaxEncoderTableCapacity()
     {
@@ -221,6 +275,33 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         this.maxEncoderTableCapacity = maxEncoderTableCapacity;
     }
 
+    @ManagedAttribute(value = "The HPACK encoder compression ratio", readonly = true)
+    public double getEncoderCompressionRatio()
+    {
+        return generator.getHpackEncoder().getCompressionRatio();
+    }
+
+    @ManagedAttribute(value = "The HPACK decoder compression ratio", readonly = true)
+    public double getDecoderCompressionRatio()
+    {
+        return parser.getHpackDecoder().getCompressionRatio();
+    }
+
+    @ManagedAttribute(value = "Whether the HPACK decoder dynamic table has been disabled", readonly = true)
+    public boolean isDecoderDynamicTableDisabled()
+    {
+        return parser.getHpackDecoder().isCompressionRatioExceeded();
+    }
+
+    private void onCompressionRatioExceeded(HpackDecoder decoder)
+    {
+        // The remote peer may be attempting a decompression bomb,
+        // so disable the dynamic table that it is allowed to use.
+        if (LOG.isDebugEnabled())
+            LOG.debug("Disabling HPACK decoder dynamic table, compression ratio {} for {}", decoder.getCompressionRatio(), this);
+        settings(new SettingsFrame(Map.of(SettingsFrame.HEADER_TABLE_SIZE, 0), false), Callback.NOOP);
+    }
+
     public EndPoint getEndPoint()
     {
         return endPoint;
@@ -326,6 +407,19 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             LOG.debug("Received {} on {}", frame, this);
     }
 
+    @Override
+    public void onPriorityUpdate(PriorityUpdateFrame frame)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Received {} on {}", frame, this);
+
+        // PRIORITY_UPDATE frames for streams that
+        // are not open yet (or anymore) are ignored.
+        IStream stream = getStream(frame.getPrioritizedStreamId());
+        if (stream != null)
+            flusher.priority(stream, ExtensiblePriority.parse(frame.getPriority()));
+    }
+
     @Override
     public void onReset(ResetFrame frame)
     {
@@ -473,7 +567,8 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
 
         if (frame.isReply())
         {
-            notifyPing(this, frame);
+            if (!flowControl.onPingReply(this, frame))
+                notifyPing(this, frame);
         }
         else
         {
@@ -1104,6 +1199,7 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
     {
         flusher.terminate(cause);
         streamTimeouts.destroy();
+        streamStallTimeouts.destroy();
         disconnect();
     }
 
@@ -1352,6 +1448,9 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             bytesWritten.addAndGet(frameBytes);
             frameBytes = 0;
 
+            if (stream != null)
+                stream.getStatistics().onFramesSent(1, 0);
+
             switch (frame.getType())
             {
                 case HEADERS:
@@ -1382,6 +1481,7 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
     {
         private int frameBytes;
         private int frameRemaining;
+        private int dataFrames;
         private int dataBytes;
         private int dataRemaining;
 
@@ -1417,7 +1517,12 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             int streamSendWindow = stream.updateSendWindow(0);
             int window = Math.min(streamSendWindow, sessionSendWindow);
             if (window <= 0 && dataRemaining > 0)
+            {
+                onStalled();
                 return false;
+            }
+
+            stream.getStatistics().onFlowControlUnstalled();
 
             int length = Math.min(dataRemaining, window);
 
@@ -1428,6 +1533,7 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             this.frameRemaining += frameBytes;
 
             int dataBytes = frameBytes - Frame.HEADER_LENGTH;
+            ++this.dataFrames;
             this.dataBytes += dataBytes;
             this.dataRemaining -= dataBytes;
             if (LOG.isDebugEnabled())
@@ -1440,6 +1546,12 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             return true;
         }
 
+        private void onStalled()
+        {
+            if (stream.getStatistics().onFlowControlStalled() && getMaxStreamStallTime() > 0)
+                streamStallTimeouts.schedule(new StreamStall((HTTP2Stream)stream));
+        }
+
         @Override
         public long onFlushed(long bytes) throws IOException
         {
@@ -1463,6 +1575,8 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
             frameRemaining = 0;
 
             flowControl.onDataSent(stream, dataBytes);
+            stream.getStatistics().onFramesSent(dataFrames, dataBytes);
+            dataFrames = 0;
             dataBytes = 0;
 
             // Do we have more to send ?
@@ -2396,6 +2510,46 @@ public abstract class HTTP2Session extends ContainerLifeCycle implements ISessio
         }
     }
 
+    private class StreamStallTimeouts extends CyclicTimeouts<StreamStall>
+    {
+        private StreamStallTimeouts(Scheduler scheduler)
+        {
+            super(scheduler);
+        }
+
+        @Override
+        protected Iterator<StreamStall> iterator()
+        {
+            return streams.values().stream().map(stream -> new StreamStall((HTTP2Stream)stream)).iterator();
+        }
+
+        @Override
+        protected boolean onExpired(StreamStall stall)
+        {
+            stall.stream.onStallExpired(getMaxStreamStallTime());
+            return false;
+        }
+    }
+
+    private class StreamStall implements CyclicTimeouts.Expirable
+    {
+        private final HTTP2Stream stream;
+
+        private StreamStall(HTTP2Stream stream)
+        {
+            this.stream = stream;
+        }
+
+        @Override
+        public long getExpireNanoTime()
+        {
+            long maxStallTime = getMaxStreamStallTime();
+            if (maxStallTime <= 0 || stream.isReset())
+                return Long.MAX_VALUE;
+            return stream.getStatistics().getStallExpireNanoTime(TimeUnit.MILLISECONDS.toNanos(maxStallTime));
+        }
+    }
+
     private class StreamTimeouts extends CyclicTimeouts<HTTP2Stream>
     {
         private StreamTimeouts(Scheduler scheduler)
@@ -18,6 +18,7 @@ import java.io.IOException;
 import java.nio.channels.WritePendingException;
 import java.util.ArrayDeque;
 import java.util.Deque;
+import java.util.Objects;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
 import java.util.concurrent.TimeUnit;
@@ -33,6 +34,7 @@ import org.eclipse.jetty.http2.api.Stream;
 import org.eclipse.jetty.http2.frames.DataFrame;
 import org.eclipse.jetty.http2.frames.FailureFrame;
 import org.eclipse.jetty.http2.frames.Frame;
+import org.eclipse.jetty.http2.frames.FrameType;
 import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.PushPromiseFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
@@ -61,6 +63,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
     private final AtomicInteger sendWindow = new AtomicInteger();
     private final AtomicInteger recvWindow = new AtomicInteger();
     private final long creationNanoTime = NanoTime.now();
+    private final HTTP2StreamStatistics statistics = new HTTP2StreamStatistics();
     private final ISession session;
     private final int streamId;
     private final MetaData.Request request;
@@ -77,6 +80,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
     private boolean committed;
     private long idleTimeout;
     private long expireNanoTime = Long.MAX_VALUE;
+    private volatile ExtensiblePriority priority;
 
     public HTTP2Stream(ISession session, int streamId, MetaData.Request request, boolean local)
     {
@@ -86,6 +90,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
         this.local = local;
         this.dataLength = Long.MIN_VALUE;
         this.dataInitial = true;
+        this.priority = ExtensiblePriority.from(request == null ? null : request.getFields());
     }
 
     @Deprecated
@@ -100,6 +105,18 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
         return streamId;
     }
 
+    @Override
+    public ExtensiblePriority getPriority()
+    {
+        return priority;
+    }
+
+    @Override
+    public void setPriority(ExtensiblePriority priority)
+    {
+        this.priority = Objects.requireNonNull(priority);
+    }
+
     @Override
     public Object getAttachment()
     {
@@ -294,6 +311,12 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
         }
     }
 
+    @Override
+    public HTTP2StreamStatistics getStatistics()
+    {
+        return statistics;
+    }
+
     public boolean isOpen()
     {
         return !isClosed();
@@ -340,6 +363,15 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
         }
     }
 
+    protected void onStallExpired(long maxStallTime)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Flow control stall {}ms exceeded {}ms on {}", statistics.getFlowControlStallTime(), maxStallTime, this);
+
+        // The peer is not reading, free the resources held by this stream.
+        reset(new ResetFrame(getId(), ErrorCode.ENHANCE_YOUR_CALM_ERROR.code), Callback.NOOP);
+    }
+
     private ConcurrentMap<String, Object> attributes()
     {
         ConcurrentMap<String, Object> map = attributes.get();
@@ -370,7 +402,10 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
     public void process(Frame frame, Callback callback)
     {
         notIdle();
-        switch (frame.getType())
+        FrameType type = frame.getType();
+        if (type != FrameType.PREFACE && type != FrameType.FAILURE)
+            statistics.onFrameReceived(type == FrameType.DATA ? ((DataFrame)frame).remaining() : 0);
+        switch (type)
         {
             case PREFACE:
             {
@@ -742,6 +777,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
 
     public void onClose()
     {
+        statistics.onFlowControlUnstalled();
         notifyClosed(this);
     }
 
@@ -931,7 +967,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
     @Override
     public String toString()
     {
-        return String.format("%s@%x#%d@%x{sendWindow=%s,recvWindow=%s,queue=%d,demand=%d,reset=%b/%b,%s,age=%d,attachment=%s}",
+        return String.format("%s@%x#%d@%x{sendWindow=%s,recvWindow=%s,queue=%d,demand=%d,reset=%b/%b,%s,age=%d,stalled=%b,attachment=%s}",
             getClass().getSimpleName(),
             hashCode(),
             getId(),
@@ -944,6 +980,7 @@ public class HTTP2Stream implements IStream, Callback, Dumpable, CyclicTimeouts.
             remoteReset,
             closeState,
             NanoTime.millisSince(creationNanoTime),
+            statistics.isFlowControlStalled(),
             attachment);
     }
 
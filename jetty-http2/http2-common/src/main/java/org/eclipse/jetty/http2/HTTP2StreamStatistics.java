@@ -0,0 +1,202 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2;
+
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+
+import org.eclipse.jetty.http2.api.Stream;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>The mutable implementation of {@link Stream.Statistics} for HTTP/2 streams.</p>
+ * <p>The update methods are invoked by the implementation as frames are
+ * sent and received, and as the stream is stalled and unstalled by flow control.</p>
+ */
+public class HTTP2StreamStatistics implements Stream.Statistics
+{
+    private final AutoLock lock = new AutoLock();
+    private final AtomicLong bytesSent = new AtomicLong();
+    private final AtomicLong bytesReceived = new AtomicLong();
+    private final AtomicLong framesSent = new AtomicLong();
+    private final AtomicLong framesReceived = new AtomicLong();
+    private final AtomicLong headOfLineBlockingNanos = new AtomicLong();
+    private long stalls;
+    private long stallNanos;
+    private long stallNanoTime;
+    private boolean stalled;
+
+    @Override
+    public long getBytesSent()
+    {
+        return bytesSent.get();
+    }
+
+    @Override
+    public long getBytesReceived()
+    {
+        return bytesReceived.get();
+    }
+
+    @Override
+    public long getFramesSent()
+    {
+        return framesSent.get();
+    }
+
+    @Override
+    public long getFramesReceived()
+    {
+        return framesReceived.get();
+    }
+
+    @Override
+    public long getFlowControlStalls()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return stalls;
+        }
+    }
+
+    @Override
+    public long getFlowControlStallTime()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(getFlowControlStallNanos());
+    }
+
+    @Override
+    public long getHeadOfLineBlockingTime()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(headOfLineBlockingNanos.get());
+    }
+
+    /**
+     * @return whether the stream is currently stalled by flow control
+     */
+    public boolean isFlowControlStalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return stalled;
+        }
+    }
+
+    /**
+     * @return the total flow control stall time in nanoseconds, including the current stall
+     */
+    public long getFlowControlStallNanos()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return stalled ? stallNanos + NanoTime.since(stallNanoTime) : stallNanos;
+        }
+    }
+
+    /**
+     * <p>Returns the nanoTime at which the total flow control stall time
+     * will exceed the given maximum, assuming the current stall continues.</p>
+     *
+     * @param maxStallNanos the maximum stall time in nanoseconds
+     * @return the nanoTime at which the maximum stall time is exceeded,
+     * or {@link Long#MAX_VALUE} if the stream is not stalled
+     */
+    public long getStallExpireNanoTime(long maxStallNanos)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (!stalled)
+                return Long.MAX_VALUE;
+            return stallNanoTime + Math.max(0, maxStallNanos - stallNanos);
+        }
+    }
+
+    /**
+     * @param frames the number of frames sent
+     * @param dataBytes the number of DATA bytes of the frames sent, or 0 for non-DATA frames
+     */
+    public void onFramesSent(int frames, int dataBytes)
+    {
+        framesSent.addAndGet(frames);
+        if (dataBytes > 0)
+            bytesSent.addAndGet(dataBytes);
+    }
+
+    /**
+     * @param dataBytes the number of DATA bytes of the frame received, or 0 for non-DATA frames
+     */
+    public void onFrameReceived(int dataBytes)
+    {
+        framesReceived.incrementAndGet();
+        if (dataBytes > 0)
+            bytesReceived.addAndGet(dataBytes);
+    }
+
+    /**
+     * <p>Records that the stream has been stalled by flow control.</p>
+     *
+     * @return whether the stream was not already stalled
+     */
+    public boolean onFlowControlStalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (stalled)
+                return false;
+            stalled = true;
+            ++stalls;
+            stallNanoTime = NanoTime.now();
+            return true;
+        }
+    }
+
+    /**
+     * <p>Records that the stream is not stalled by flow control anymore.</p>
+     */
+    public void onFlowControlUnstalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (!stalled)
+                return;
+            stalled = false;
+            stallNanos += NanoTime.since(stallNanoTime);
+        }
+    }
+
+    /**
+     * @param nanos the time, in nanoseconds, a frame waited to be written
+     */
+    public void onHeadOfLineBlocked(long nanos)
+    {
+        if (nanos > 0)
+            headOfLineBlockingNanos.addAndGet(nanos);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{sent=%d/%d,received=%d/%d,stalls=%d/%dms,hol=%dms}",
+            getClass().getSimpleName(),
+            hashCode(),
+            getFramesSent(),
+            getBytesSent(),
+            getFramesReceived(),
+            getBytesReceived(),
+            getFlowControlStalls(),
+            getFlowControlStallTime(),
+            getHeadOfLineBlockingTime());
+    }
+}
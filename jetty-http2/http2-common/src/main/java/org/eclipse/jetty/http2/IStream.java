@@ -129,6 +129,25 @@ public interface IStream extends Stream, Attachable, Closeable
      */
     boolean isResetOrFailed();
 
+    /**
+     * @return the RFC 9218 priority of this stream
+     */
+    default ExtensiblePriority getPriority()
+    {
+        return ExtensiblePriority.DEFAULT;
+    }
+
+    /**
+     * <p>Sets the RFC 9218 priority of this stream.</p>
+     * <p>This method is invoked by {@link HTTP2Flusher}, so that priority
+     * changes do not happen concurrently with the scheduling of frames.</p>
+     *
+     * @param priority the new priority of this stream
+     */
+    default void setPriority(ExtensiblePriority priority)
+    {
+    }
+
     /**
      * Marks this stream as committed.
      *
@@ -147,6 +166,9 @@ public interface IStream extends Stream, Attachable, Closeable
      */
     int dataSize();
 
+    @Override
+    public HTTP2StreamStatistics getStatistics();
+
     /**
      * <p>An ordered list of frames belonging to the same stream.</p>
      */
@@ -0,0 +1,63 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2;
+
+/**
+ * <p>Decides which streams can write DATA frames, when multiple streams have data to write.</p>
+ * <p>Before every pass over the pending frames, {@link HTTP2Flusher} selects the most
+ * important stream that can make progress, as determined by {@link #compare(IStream, IStream)};
+ * DATA frames of streams that compare after the selected stream are not written in that pass,
+ * so that they are written only after the more important stream stops making progress,
+ * for example because it has no more data to write or it is flow control stalled.
+ * Streams that compare equal share the bandwidth in a round-robin fashion.
+ * Frames other than DATA are always written in the order they have been queued.</p>
+ * <p>Implementations must be fast since they are invoked for every pass.</p>
+ */
+public interface StreamPrioritizer
+{
+    /**
+     * @param stream1 the first stream
+     * @param stream2 the second stream
+     * @return a negative number if the data of {@code stream1} must be written before the data of
+     * {@code stream2}, a positive number if after, or zero if the streams share the bandwidth
+     */
+    int compare(IStream stream1, IStream stream2);
+
+    /**
+     * <p>The {@link StreamPrioritizer} that implements the scheduling recommended by RFC 9218:</p>
+     * <ul>
+     * <li>streams with a lower urgency are written before streams with a higher urgency</li>
+     * <li>among streams with the same urgency, non-incremental streams are written one at a time,
+     * in stream id order, before the incremental streams</li>
+     * <li>incremental streams with the same urgency share the bandwidth</li>
+     * </ul>
+     */
+    class Extensible implements StreamPrioritizer
+    {
+        @Override
+        public int compare(IStream stream1, IStream stream2)
+        {
+            ExtensiblePriority priority1 = stream1.getPriority();
+            ExtensiblePriority priority2 = stream2.getPriority();
+            int result = Integer.compare(priority1.getUrgency(), priority2.getUrgency());
+            if (result != 0)
+                return result;
+            if (priority1.isIncremental() != priority2.isIncremental())
+                return priority1.isIncremental() ? 1 : -1;
+            if (priority1.isIncremental())
+                return 0;
+            return Integer.compare(stream1.getId(), stream2.getId());
+        }
+    }
+}
@@ -175,6 +175,11 @@ public interface Stream
      */
     public void demand(long n);
 
+    /**
+     * @return the statistics of this stream
+     */
+    public Statistics getStatistics();
+
     /**
      * <p>A {@link Stream.Listener} is the passive counterpart of a {@link Stream} and receives
      * events happening on an HTTP/2 stream.</p>
@@ -355,4 +360,52 @@ public interface Stream
             }
         }
     }
+
+    /**
+     * <p>The statistics of a {@link Stream}.</p>
+     * <p>The statistics are updated while the stream is active, and
+     * retain their last values after the stream is closed.</p>
+     */
+    public interface Statistics
+    {
+        /**
+         * @return the number of bytes of DATA frames sent
+         */
+        public long getBytesSent();
+
+        /**
+         * @return the number of bytes of DATA frames received
+         */
+        public long getBytesReceived();
+
+        /**
+         * @return the number of frames sent
+         */
+        public long getFramesSent();
+
+        /**
+         * @return the number of frames received
+         */
+        public long getFramesReceived();
+
+        /**
+         * @return the number of times the stream has been stalled by flow control
+         */
+        public long getFlowControlStalls();
+
+        /**
+         * @return the total time, in milliseconds, the stream has been stalled
+         * by flow control, including the current stall if the stream is stalled
+         */
+        public long getFlowControlStallTime();
+
+        /**
+         * <p>Returns the total time, in milliseconds, that frames of the stream
+         * waited to be written, queued behind frames of other streams.</p>
+         * <p>The time spent stalled by flow control is not included.</p>
+         *
+         * @return the total head-of-line blocking time in milliseconds
+         */
+        public long getHeadOfLineBlockingTime();
+    }
 }
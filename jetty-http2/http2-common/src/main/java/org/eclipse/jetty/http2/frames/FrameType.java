@@ -28,6 +28,8 @@ public enum FrameType
     GO_AWAY(7),
     WINDOW_UPDATE(8),
     CONTINUATION(9),
+    // RFC 9218.
+    PRIORITY_UPDATE(16),
     // Synthetic frames only needed by the implementation.
     PREFACE(10),
     DISCONNECT(11),
@@ -38,6 +40,19 @@ public enum FrameType
         return Types.types.get(type);
     }
 
+    /**
+     * @return the max frame type code, useful to size arrays indexed by frame type code
+     */
+    public static int getMaxType()
+    {
+        int max = 0;
+        for (FrameType frameType : values())
+        {
+            max = Math.max(max, frameType.getType());
+        }
+        return max;
+    }
+
     private final int type;
 
     private FrameType(int type)
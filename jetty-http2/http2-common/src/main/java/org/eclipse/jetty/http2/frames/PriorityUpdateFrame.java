@@ -0,0 +1,51 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.frames;
+
+/**
+ * <p>The PRIORITY_UPDATE frame defined by RFC 9218, that carries the
+ * new priority of a request stream, in the format of the
+ * {@code Priority} HTTP header value.</p>
+ */
+public class PriorityUpdateFrame extends Frame
+{
+    private final int prioritizedStreamId;
+    private final String priority;
+
+    public PriorityUpdateFrame(int prioritizedStreamId, String priority)
+    {
+        super(FrameType.PRIORITY_UPDATE);
+        this.prioritizedStreamId = prioritizedStreamId;
+        this.priority = priority;
+    }
+
+    public int getPrioritizedStreamId()
+    {
+        return prioritizedStreamId;
+    }
+
+    /**
+     * @return the priority field value, for example {@code u=1, i}
+     */
+    public String getPriority()
+    {
+        return priority;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s#%d{%s}", super.toString(), prioritizedStreamId, priority);
+    }
+}
@@ -57,7 +57,7 @@ public class Generator
         headerGenerator = new HeaderGenerator(useDirectByteBuffers);
         hpackEncoder = new HpackEncoder();
 
-        this.generators = new FrameGenerator[FrameType.values().length];
+        this.generators = new FrameGenerator[FrameType.getMaxType() + 1];
         this.generators[FrameType.HEADERS.getType()] = new HeadersGenerator(headerGenerator, hpackEncoder, maxHeaderBlockFragment);
         this.generators[FrameType.PRIORITY.getType()] = new PriorityGenerator(headerGenerator);
         this.generators[FrameType.RST_STREAM.getType()] = new ResetGenerator(headerGenerator);
@@ -67,6 +67,7 @@ public class Generator
         this.generators[FrameType.GO_AWAY.getType()] = new GoAwayGenerator(headerGenerator);
         this.generators[FrameType.WINDOW_UPDATE.getType()] = new WindowUpdateGenerator(headerGenerator);
         this.generators[FrameType.CONTINUATION.getType()] = null; // Never generated explicitly.
+        this.generators[FrameType.PRIORITY_UPDATE.getType()] = new PriorityUpdateGenerator(headerGenerator);
         this.generators[FrameType.PREFACE.getType()] = new PrefaceGenerator();
         this.generators[FrameType.DISCONNECT.getType()] = new NoOpGenerator();
 
@@ -0,0 +1,57 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.generator;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+
+import org.eclipse.jetty.http2.Flags;
+import org.eclipse.jetty.http2.frames.Frame;
+import org.eclipse.jetty.http2.frames.FrameType;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+
+public class PriorityUpdateGenerator extends FrameGenerator
+{
+    public PriorityUpdateGenerator(HeaderGenerator headerGenerator)
+    {
+        super(headerGenerator);
+    }
+
+    @Override
+    public int generate(ByteBufferPool.Lease lease, Frame frame)
+    {
+        PriorityUpdateFrame priorityUpdateFrame = (PriorityUpdateFrame)frame;
+        return generatePriorityUpdate(lease, priorityUpdateFrame.getPrioritizedStreamId(), priorityUpdateFrame.getPriority());
+    }
+
+    public int generatePriorityUpdate(ByteBufferPool.Lease lease, int prioritizedStreamId, String priority)
+    {
+        if (prioritizedStreamId <= 0)
+            throw new IllegalArgumentException("Invalid prioritized stream id: " + prioritizedStreamId);
+
+        byte[] payload = priority == null ? new byte[0] : priority.getBytes(StandardCharsets.US_ASCII);
+        int length = 4 + payload.length;
+        if (length > getMaxFrameSize())
+            throw new IllegalArgumentException("Invalid priority, too big");
+
+        ByteBuffer header = generateHeader(lease, FrameType.PRIORITY_UPDATE, length, Flags.NONE, 0);
+        header.putInt(prioritizedStreamId);
+        header.put(payload);
+        BufferUtil.flipToFlush(header, 0);
+        lease.append(header, true);
+        return Frame.HEADER_LENGTH + length;
+    }
+}
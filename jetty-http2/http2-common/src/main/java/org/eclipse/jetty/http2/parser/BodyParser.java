@@ -22,6 +22,7 @@ import org.eclipse.jetty.http2.frames.GoAwayFrame;
 import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.PriorityFrame;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
 import org.eclipse.jetty.http2.frames.PushPromiseFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
@@ -132,6 +133,18 @@ public abstract class BodyParser
         }
     }
 
+    protected void notifyPriorityUpdate(PriorityUpdateFrame frame)
+    {
+        try
+        {
+            listener.onPriorityUpdate(frame);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
     protected void notifyReset(ResetFrame frame)
     {
         try
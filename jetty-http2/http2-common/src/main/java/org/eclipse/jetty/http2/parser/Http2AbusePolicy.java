@@ -0,0 +1,426 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.parser;
+
+import java.time.Duration;
+import java.util.EnumMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.TreeMap;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http2.frames.ContinuationFrame;
+import org.eclipse.jetty.http2.frames.DataFrame;
+import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.http2.frames.ResetFrame;
+import org.eclipse.jetty.http2.frames.SettingsFrame;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link RateControl.Factory} that detects abusive HTTP/2 clients.</p>
+ * <p>Events signaled by the parser are classified into {@link Violation}
+ * types, each with its own maximum rate within a time window.
+ * When a connection exceeds the rate for a type, its score is incremented
+ * by the weight of that type, and the response escalates with the score:</p>
+ * <ul>
+ * <li>below the {@link #getGoAwayScore() GOAWAY score}, the event is allowed,
+ * but the parsing of the connection is delayed by {@link #getDelay()}
+ * multiplied by the score;</li>
+ * <li>at or above the GOAWAY score, the event is rejected and the connection
+ * is closed with a {@code GOAWAY} frame with error {@code ENHANCE_YOUR_CALM};</li>
+ * <li>when reaching the {@link #getBlockScore() block score}, {@link Listener}s are
+ * also notified via {@link Listener#onBlock(EndPoint, Violation)}, typically
+ * to block further connections from the remote address.</li>
+ * </ul>
+ * <p>The delay is applied by the thread that parses the connection, so that
+ * it is not available to read other connections while it waits; large delays
+ * should therefore be avoided.</p>
+ * <p>With the default configuration, the first violation closes the connection,
+ * which is equivalent to {@link WindowRateControl}.</p>
+ */
+@ManagedObject("HTTP/2 abuse policy")
+public class Http2AbusePolicy implements RateControl.Factory
+{
+    private static final Logger LOG = LoggerFactory.getLogger(Http2AbusePolicy.class);
+
+    private final Map<Violation, Integer> maxEvents = new EnumMap<>(Violation.class);
+    private final Map<Violation, Integer> weights = new EnumMap<>(Violation.class);
+    private final Map<Violation, LongAdder> violations = new EnumMap<>(Violation.class);
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
+    private final LongAdder delayed = new LongAdder();
+    private final LongAdder goAways = new LongAdder();
+    private final LongAdder blocks = new LongAdder();
+    private Duration window = Duration.ofSeconds(1);
+    private Duration delay = Duration.ZERO;
+    private int goAwayScore = 1;
+    private int blockScore;
+
+    public Http2AbusePolicy()
+    {
+        this(50);
+    }
+
+    /**
+     * @param maxEventRate the default maximum number of events per window for all violation types
+     */
+    public Http2AbusePolicy(int maxEventRate)
+    {
+        for (Violation violation : Violation.values())
+        {
+            maxEvents.put(violation, maxEventRate);
+            weights.put(violation, 1);
+            violations.put(violation, new LongAdder());
+        }
+    }
+
+    /**
+     * @param violation the violation type
+     * @return the maximum number of events of the given type allowed within the window
+     */
+    public int getMaxEvents(Violation violation)
+    {
+        return maxEvents.get(violation);
+    }
+
+    /**
+     * @param violation the violation type
+     * @param maxEvents the maximum number of events of the given type allowed within the window
+     */
+    public void setMaxEvents(Violation violation, int maxEvents)
+    {
+        if (maxEvents < 0)
+            throw new IllegalArgumentException("Invalid max events " + maxEvents);
+        this.maxEvents.put(Objects.requireNonNull(violation), maxEvents);
+    }
+
+    /**
+     * @param violation the violation type
+     * @return the score added to a connection when it exceeds the rate for the given type
+     */
+    public int getWeight(Violation violation)
+    {
+        return weights.get(violation);
+    }
+
+    /**
+     * @param violation the violation type
+     * @param weight the score added to a connection when it exceeds the rate for the given type
+     */
+    public void setWeight(Violation violation, int weight)
+    {
+        if (weight < 0)
+            throw new IllegalArgumentException("Invalid weight " + weight);
+        weights.put(Objects.requireNonNull(violation), weight);
+    }
+
+    @ManagedAttribute("The time window within which events are counted")
+    public Duration getWindow()
+    {
+        return window;
+    }
+
+    public void setWindow(Duration window)
+    {
+        if (window.isZero() || window.isNegative())
+            throw new IllegalArgumentException("Invalid window " + window);
+        this.window = window;
+    }
+
+    @ManagedAttribute("The delay per score point applied to connections below the GOAWAY score")
+    public Duration getDelay()
+    {
+        return delay;
+    }
+
+    public void setDelay(Duration delay)
+    {
+        this.delay = Objects.requireNonNull(delay);
+    }
+
+    @ManagedAttribute("The score at which connections are closed with GOAWAY")
+    public int getGoAwayScore()
+    {
+        return goAwayScore;
+    }
+
+    public void setGoAwayScore(int goAwayScore)
+    {
+        if (goAwayScore < 1)
+            throw new IllegalArgumentException("Invalid GOAWAY score " + goAwayScore);
+        this.goAwayScore = goAwayScore;
+    }
+
+    @ManagedAttribute("The score at which listeners are notified to block the remote peer, or 0 to never block")
+    public int getBlockScore()
+    {
+        return blockScore;
+    }
+
+    public void setBlockScore(int blockScore)
+    {
+        if (blockScore < 0)
+            throw new IllegalArgumentException("Invalid block score " + blockScore);
+        this.blockScore = blockScore;
+    }
+
+    public void addListener(Listener listener)
+    {
+        listeners.add(listener);
+    }
+
+    public void removeListener(Listener listener)
+    {
+        listeners.remove(listener);
+    }
+
+    /**
+     * @param violation the violation type
+     * @return the number of events of the given type that exceeded the rate
+     */
+    public long getViolations(Violation violation)
+    {
+        return violations.get(violation).sum();
+    }
+
+    @ManagedAttribute("The number of violations per type")
+    public Map<String, Long> getViolations()
+    {
+        Map<String, Long> result = new TreeMap<>();
+        violations.forEach((violation, count) -> result.put(violation.name(), count.sum()));
+        return result;
+    }
+
+    @ManagedAttribute("The number of violations that delayed the connection")
+    public long getDelayed()
+    {
+        return delayed.sum();
+    }
+
+    @ManagedAttribute("The number of connections closed with GOAWAY")
+    public long getGoAways()
+    {
+        return goAways.sum();
+    }
+
+    @ManagedAttribute("The number of remote peers reported to be blocked")
+    public long getBlocks()
+    {
+        return blocks.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void statsReset()
+    {
+        violations.values().forEach(LongAdder::reset);
+        delayed.reset();
+        goAways.reset();
+        blocks.reset();
+    }
+
+    @Override
+    public RateControl newRateControl(EndPoint endPoint)
+    {
+        return new Control(endPoint);
+    }
+
+    /**
+     * <p>Classifies the given event, as signaled by the parser, into a violation type.</p>
+     *
+     * @param event the event subject to rate control
+     * @return the violation type of the event
+     */
+    protected Violation classify(Object event)
+    {
+        if (event instanceof ResetFrame)
+            return Violation.RST_STREAM;
+        if (event instanceof HeadersFrame || event instanceof ContinuationFrame)
+            return Violation.HEADERS;
+        if (event instanceof SettingsFrame)
+            return Violation.SETTINGS;
+        if (event instanceof PingFrame)
+            return Violation.PING;
+        if (event instanceof DataFrame)
+            return Violation.EMPTY_DATA;
+        return Violation.OTHER;
+    }
+
+    private void notifyViolation(EndPoint endPoint, Violation violation, int score)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onViolation(endPoint, violation, score);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyBlock(EndPoint endPoint, Violation violation)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onBlock(endPoint, violation);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{window=%s,goAway=%d,block=%d,violations=%s}",
+            getClass().getSimpleName(), hashCode(), getWindow(), getGoAwayScore(), getBlockScore(), getViolations());
+    }
+
+    /**
+     * <p>The types of events subject to rate control.</p>
+     */
+    public enum Violation
+    {
+        /**
+         * RST_STREAM frames, as sent by rapid reset attacks.
+         */
+        RST_STREAM,
+        /**
+         * HEADERS and CONTINUATION frames that are empty, invalid or fragmented.
+         */
+        HEADERS,
+        /**
+         * Non-ACK SETTINGS frames.
+         */
+        SETTINGS,
+        /**
+         * PING frames.
+         */
+        PING,
+        /**
+         * Zero-length DATA frames that do not end the stream.
+         */
+        EMPTY_DATA,
+        /**
+         * Other frames, such as PRIORITY and unknown frames.
+         */
+        OTHER
+    }
+
+    /**
+     * <p>A listener for abuse events.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Invoked when a connection exceeds the rate for a violation type.</p>
+         *
+         * @param endPoint the EndPoint of the connection
+         * @param violation the violation type
+         * @param score the connection score, including this violation
+         */
+        public default void onViolation(EndPoint endPoint, Violation violation, int score)
+        {
+        }
+
+        /**
+         * <p>Invoked when a connection reaches the block score.</p>
+         * <p>Implementations may block further connections from
+         * {@link EndPoint#getRemoteSocketAddress()}.</p>
+         *
+         * @param endPoint the EndPoint of the connection
+         * @param violation the violation type that caused the block
+         */
+        public default void onBlock(EndPoint endPoint, Violation violation)
+        {
+        }
+    }
+
+    private class Control implements RateControl
+    {
+        private final Map<Violation, WindowRateControl> rates = new EnumMap<>(Violation.class);
+        private final EndPoint endPoint;
+        private int score;
+
+        private Control(EndPoint endPoint)
+        {
+            this.endPoint = endPoint;
+            for (Violation violation : Violation.values())
+            {
+                rates.put(violation, new WindowRateControl(getMaxEvents(violation), getWindow()));
+            }
+        }
+
+        @Override
+        public boolean onEvent(Object event)
+        {
+            Violation violation = classify(event);
+            if (rates.get(violation).onEvent(event))
+                return true;
+
+            violations.get(violation).increment();
+            int weight = getWeight(violation);
+            int score;
+            synchronized (this)
+            {
+                score = this.score += weight;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} violation, score {} for {}", violation, score, endPoint);
+            notifyViolation(endPoint, violation, score);
+
+            int block = getBlockScore();
+            if (block > 0 && score >= block && score - weight < block)
+            {
+                blocks.increment();
+                notifyBlock(endPoint, violation);
+            }
+
+            if (score >= getGoAwayScore())
+            {
+                goAways.increment();
+                return false;
+            }
+
+            long pause = getDelay().toNanos() * score;
+            if (pause > 0)
+            {
+                delayed.increment();
+                try
+                {
+                    TimeUnit.NANOSECONDS.sleep(pause);
+                }
+                catch (InterruptedException x)
+                {
+                    return false;
+                }
+            }
+            return true;
+        }
+    }
+}
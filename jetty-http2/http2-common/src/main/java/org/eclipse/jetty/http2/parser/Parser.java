@@ -24,6 +24,7 @@ import org.eclipse.jetty.http2.frames.GoAwayFrame;
 import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.PriorityFrame;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
 import org.eclipse.jetty.http2.frames.PushPromiseFrame;
 import org.eclipse.jetty.http2.frames.ResetFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
@@ -75,7 +76,7 @@ public class Parser
         this.byteBufferPool = byteBufferPool;
         this.headerParser = new HeaderParser(rateControl == null ? RateControl.NO_RATE_CONTROL : rateControl);
         this.hpackDecoder = new HpackDecoder(maxHeaderSize);
-        this.bodyParsers = new BodyParser[FrameType.values().length];
+        this.bodyParsers = new BodyParser[FrameType.getMaxType() + 1];
     }
 
     public void init(Listener listener)
@@ -96,6 +97,7 @@ public class Parser
         bodyParsers[FrameType.GO_AWAY.getType()] = new GoAwayBodyParser(headerParser, listener);
         bodyParsers[FrameType.WINDOW_UPDATE.getType()] = new WindowUpdateBodyParser(headerParser, listener);
         bodyParsers[FrameType.CONTINUATION.getType()] = new ContinuationBodyParser(headerParser, listener, headerBlockParser, headerBlockFragments);
+        bodyParsers[FrameType.PRIORITY_UPDATE.getType()] = new PriorityUpdateBodyParser(headerParser, listener);
     }
 
     protected Listener getListener()
@@ -194,7 +196,7 @@ public class Parser
     protected boolean parseBody(ByteBuffer buffer)
     {
         int type = getFrameType();
-        if (type < 0 || type >= bodyParsers.length)
+        if (type < 0 || type >= bodyParsers.length || bodyParsers[type] == null)
         {
             // Unknown frame types must be ignored.
             if (LOG.isDebugEnabled())
@@ -300,6 +302,10 @@ public class Parser
 
         public void onWindowUpdate(WindowUpdateFrame frame);
 
+        public default void onPriorityUpdate(PriorityUpdateFrame frame)
+        {
+        }
+
         public void onStreamFailure(int streamId, int error, String reason);
 
         public void onConnectionFailure(int error, String reason);
@@ -431,6 +437,12 @@ public class Parser
                 listener.onWindowUpdate(frame);
             }
 
+            @Override
+            public void onPriorityUpdate(PriorityUpdateFrame frame)
+            {
+                listener.onPriorityUpdate(frame);
+            }
+
             @Override
             public void onStreamFailure(int streamId, int error, String reason)
             {
@@ -0,0 +1,144 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.parser;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+
+import org.eclipse.jetty.http2.ErrorCode;
+import org.eclipse.jetty.http2.frames.PriorityUpdateFrame;
+
+public class PriorityUpdateBodyParser extends BodyParser
+{
+    private State state = State.PREPARE;
+    private int cursor;
+    private int length;
+    private int prioritizedStreamId;
+    private byte[] payload;
+
+    public PriorityUpdateBodyParser(HeaderParser headerParser, Parser.Listener listener)
+    {
+        super(headerParser, listener);
+    }
+
+    private void reset()
+    {
+        state = State.PREPARE;
+        cursor = 0;
+        length = 0;
+        prioritizedStreamId = 0;
+        payload = null;
+    }
+
+    @Override
+    public boolean parse(ByteBuffer buffer)
+    {
+        while (buffer.hasRemaining())
+        {
+            switch (state)
+            {
+                case PREPARE:
+                {
+                    // SPEC: RFC 9218 section 7.1, wrong streamId is treated as connection error.
+                    if (getStreamId() != 0)
+                        return connectionFailure(buffer, ErrorCode.PROTOCOL_ERROR.code, "invalid_priority_update_frame");
+                    length = getBodyLength();
+                    if (length < 4)
+                        return connectionFailure(buffer, ErrorCode.FRAME_SIZE_ERROR.code, "invalid_priority_update_frame");
+                    state = State.PRIORITIZED_STREAM_ID;
+                    break;
+                }
+                case PRIORITIZED_STREAM_ID:
+                {
+                    if (buffer.remaining() >= 4)
+                    {
+                        prioritizedStreamId = buffer.getInt();
+                        prioritizedStreamId &= 0x7F_FF_FF_FF;
+                        length -= 4;
+                        if (prioritizedStreamId == 0)
+                            return connectionFailure(buffer, ErrorCode.PROTOCOL_ERROR.code, "invalid_priority_update_frame");
+                        state = State.PAYLOAD;
+                        if (length == 0)
+                            return onPriorityUpdate();
+                    }
+                    else
+                    {
+                        state = State.PRIORITIZED_STREAM_ID_BYTES;
+                        cursor = 4;
+                    }
+                    break;
+                }
+                case PRIORITIZED_STREAM_ID_BYTES:
+                {
+                    int currByte = buffer.get() & 0xFF;
+                    --cursor;
+                    prioritizedStreamId += currByte << (8 * cursor);
+                    --length;
+                    if (cursor == 0)
+                    {
+                        prioritizedStreamId &= 0x7F_FF_FF_FF;
+                        if (prioritizedStreamId == 0)
+                            return connectionFailure(buffer, ErrorCode.PROTOCOL_ERROR.code, "invalid_priority_update_frame");
+                        state = State.PAYLOAD;
+                        if (length == 0)
+                            return onPriorityUpdate();
+                    }
+                    break;
+                }
+                case PAYLOAD:
+                {
+                    payload = new byte[length];
+                    if (buffer.remaining() >= length)
+                    {
+                        buffer.get(payload);
+                        return onPriorityUpdate();
+                    }
+                    else
+                    {
+                        state = State.PAYLOAD_BYTES;
+                        cursor = length;
+                    }
+                    break;
+                }
+                case PAYLOAD_BYTES:
+                {
+                    payload[payload.length - cursor] = buffer.get();
+                    --cursor;
+                    if (cursor == 0)
+                        return onPriorityUpdate();
+                    break;
+                }
+                default:
+                {
+                    throw new IllegalStateException();
+                }
+            }
+        }
+        return false;
+    }
+
+    private boolean onPriorityUpdate()
+    {
+        String priority = payload == null ? "" : new String(payload, StandardCharsets.US_ASCII);
+        PriorityUpdateFrame frame = new PriorityUpdateFrame(prioritizedStreamId, priority);
+        reset();
+        notifyPriorityUpdate(frame);
+        return true;
+    }
+
+    private enum State
+    {
+        PREPARE, PRIORITIZED_STREAM_ID, PRIORITIZED_STREAM_ID_BYTES, PAYLOAD, PAYLOAD_BYTES
+    }
+}
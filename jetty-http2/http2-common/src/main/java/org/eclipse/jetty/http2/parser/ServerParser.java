@@ -17,6 +17,7 @@ import java.nio.ByteBuffer;
 
 import org.eclipse.jetty.http2.ErrorCode;
 import org.eclipse.jetty.http2.Flags;
+import org.eclipse.jetty.http2.frames.DataFrame;
 import org.eclipse.jetty.http2.frames.FrameType;
 import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.util.BufferUtil;
@@ -30,6 +31,7 @@ public class ServerParser extends Parser
     private PrefaceParser prefaceParser;
     private State state = State.PREFACE;
     private boolean notifyPreface = true;
+    private long upgradeContentRemaining;
 
     @Deprecated
     public ServerParser(ByteBufferPool byteBufferPool, int maxTableSize, int maxHeaderSize, RateControl rateControl)
@@ -84,6 +86,24 @@ public class ServerParser extends Parser
         notifyPreface = false;
     }
 
+    /**
+     * <p>The standard HTTP/1.1 upgrade path, for an upgrade request with content.</p>
+     * <p>The content of the upgrade request is sent by the client before the
+     * HTTP/2 preface, and it is notified as DATA frames of stream 1, the stream
+     * of the upgrade request.</p>
+     *
+     * @param contentLength the content length of the upgrade request
+     */
+    public void standardUpgrade(long contentLength)
+    {
+        standardUpgrade();
+        if (contentLength > 0)
+        {
+            upgradeContentRemaining = contentLength;
+            state = State.UPGRADE_CONTENT;
+        }
+    }
+
     @Override
     public void parse(ByteBuffer buffer)
     {
@@ -96,6 +116,13 @@ public class ServerParser extends Parser
             {
                 switch (state)
                 {
+                    case UPGRADE_CONTENT:
+                    {
+                        if (!parseUpgradeContent(buffer))
+                            return;
+                        state = State.PREFACE;
+                        break;
+                    }
                     case PREFACE:
                     {
                         if (!prefaceParser.parse(buffer))
@@ -141,6 +168,34 @@ public class ServerParser extends Parser
         }
     }
 
+    private boolean parseUpgradeContent(ByteBuffer buffer)
+    {
+        int length = (int)Math.min(buffer.remaining(), upgradeContentRemaining);
+        if (length == 0)
+            return false;
+        // The frame payload is a slice of the network buffer, like for DATA frames.
+        ByteBuffer content = buffer.slice();
+        content.limit(length);
+        buffer.position(buffer.position() + length);
+        upgradeContentRemaining -= length;
+        boolean last = upgradeContentRemaining == 0;
+        notifyUpgradeContent(new DataFrame(1, content, last));
+        return last;
+    }
+
+    private void notifyUpgradeContent(DataFrame frame)
+    {
+        Listener listener = getListener();
+        try
+        {
+            listener.onData(frame);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
     protected void onPreface()
     {
         notifyPreface();
@@ -194,6 +249,6 @@ public class ServerParser extends Parser
 
     private enum State
     {
-        PREFACE, SETTINGS, FRAMES
+        UPGRADE_CONTENT, PREFACE, SETTINGS, FRAMES
     }
 }
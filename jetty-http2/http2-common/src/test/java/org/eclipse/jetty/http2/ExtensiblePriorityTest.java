@@ -0,0 +1,85 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.hamcrest.Matchers.sameInstance;
+
+public class ExtensiblePriorityTest
+{
+    @Test
+    public void testParse()
+    {
+        assertThat(ExtensiblePriority.parse(null), sameInstance(ExtensiblePriority.DEFAULT));
+        assertThat(ExtensiblePriority.parse(""), sameInstance(ExtensiblePriority.DEFAULT));
+        assertThat(ExtensiblePriority.parse("u=1"), is(new ExtensiblePriority(1, false)));
+        assertThat(ExtensiblePriority.parse("u=5, i"), is(new ExtensiblePriority(5, true)));
+        assertThat(ExtensiblePriority.parse("i=?1"), is(new ExtensiblePriority(3, true)));
+        assertThat(ExtensiblePriority.parse("u=0, i=?0"), is(new ExtensiblePriority(0, false)));
+        // Parameters are ignored.
+        assertThat(ExtensiblePriority.parse("u=2;foo=bar, i"), is(new ExtensiblePriority(2, true)));
+        // Unknown members and invalid values are ignored.
+        assertThat(ExtensiblePriority.parse("x=1, u=9, i=2"), sameInstance(ExtensiblePriority.DEFAULT));
+        assertThat(ExtensiblePriority.parse("u=abc"), sameInstance(ExtensiblePriority.DEFAULT));
+        // Later members override earlier ones.
+        assertThat(ExtensiblePriority.parse("u=1, u=6"), is(new ExtensiblePriority(6, false)));
+    }
+
+    @Test
+    public void testFromHttpFields()
+    {
+        HttpFields fields = HttpFields.build()
+            .add(ExtensiblePriority.PRIORITY_HEADER, "u=1")
+            .add(ExtensiblePriority.PRIORITY_HEADER, "i");
+        assertThat(ExtensiblePriority.from(fields), is(new ExtensiblePriority(1, true)));
+        assertThat(ExtensiblePriority.from(HttpFields.EMPTY), sameInstance(ExtensiblePriority.DEFAULT));
+    }
+
+    @Test
+    public void testHeaderValueRoundTrip()
+    {
+        ExtensiblePriority priority = new ExtensiblePriority(6, true);
+        assertThat(ExtensiblePriority.parse(priority.toHeaderValue()), is(priority));
+    }
+
+    @Test
+    public void testExtensibleStreamPrioritizer()
+    {
+        StreamPrioritizer prioritizer = new StreamPrioritizer.Extensible();
+        IStream urgent = newStream(5, new ExtensiblePriority(0, false));
+        IStream normal1 = newStream(1, ExtensiblePriority.DEFAULT);
+        IStream normal3 = newStream(3, ExtensiblePriority.DEFAULT);
+        IStream incremental7 = newStream(7, new ExtensiblePriority(3, true));
+        IStream incremental9 = newStream(9, new ExtensiblePriority(3, true));
+
+        assertThat(prioritizer.compare(urgent, normal1), lessThan(0));
+        assertThat(prioritizer.compare(normal1, normal3), lessThan(0));
+        assertThat(prioritizer.compare(normal3, incremental7), lessThan(0));
+        assertThat(prioritizer.compare(incremental9, normal1), greaterThan(0));
+        assertThat(prioritizer.compare(incremental7, incremental9), is(0));
+    }
+
+    private static IStream newStream(int streamId, ExtensiblePriority priority)
+    {
+        HTTP2Stream stream = new HTTP2Stream(null, streamId, null, true);
+        stream.setPriority(priority);
+        return stream;
+    }
+}
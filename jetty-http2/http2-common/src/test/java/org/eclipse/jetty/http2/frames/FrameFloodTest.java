@@ -95,6 +95,13 @@ public class FrameFloodTest
         testFrameFlood(null, frameFrom(payload.length, FrameType.SETTINGS.getType(), 0, 0, payload));
     }
 
+    @Test
+    public void testResetFrameFlood()
+    {
+        byte[] payload = {0, 0, 0, 8};
+        testFrameFlood(null, frameFrom(payload.length, FrameType.RST_STREAM.getType(), 0, 13, payload));
+    }
+
     @Test
     public void testPingFrameFlood()
     {
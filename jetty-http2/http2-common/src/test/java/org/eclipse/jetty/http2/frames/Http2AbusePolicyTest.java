@@ -0,0 +1,149 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.frames;
+
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.http2.ErrorCode;
+import org.eclipse.jetty.http2.parser.Http2AbusePolicy;
+import org.eclipse.jetty.http2.parser.Http2AbusePolicy.Violation;
+import org.eclipse.jetty.http2.parser.Parser;
+import org.eclipse.jetty.io.ByteArrayEndPoint;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.MappedByteBufferPool;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.hamcrest.Matchers.sameInstance;
+
+public class Http2AbusePolicyTest
+{
+    private static final byte[] PING = {0, 0, 8, (byte)FrameType.PING.getType(), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0};
+    private static final byte[] RESET = {0, 0, 4, (byte)FrameType.RST_STREAM.getType(), 0, 0, 0, 0, 13, 0, 0, 0, 8};
+
+    private final ByteBufferPool byteBufferPool = new MappedByteBufferPool();
+    private final EndPoint endPoint = new ByteArrayEndPoint();
+    private final AtomicReference<Integer> failure = new AtomicReference<>();
+
+    private Parser newParser(Http2AbusePolicy policy)
+    {
+        Parser parser = new Parser(byteBufferPool, 8192, policy.newRateControl(endPoint));
+        parser.init(new Parser.Listener.Adapter()
+        {
+            @Override
+            public void onConnectionFailure(int error, String reason)
+            {
+                failure.set(error);
+            }
+        });
+        return parser;
+    }
+
+    private int parseUntilFailure(Parser parser, byte[] bytes, int max)
+    {
+        int count = 0;
+        while (failure.get() == null && count < max)
+        {
+            parser.parse(ByteBuffer.wrap(bytes));
+            ++count;
+        }
+        return count;
+    }
+
+    @Test
+    public void testDefaultConfigurationClosesOnFirstViolation()
+    {
+        Http2AbusePolicy policy = new Http2AbusePolicy(4);
+        Parser parser = newParser(policy);
+
+        assertThat(parseUntilFailure(parser, PING, 100), is(5));
+        assertThat(failure.get(), is(ErrorCode.ENHANCE_YOUR_CALM_ERROR.code));
+        assertThat(policy.getViolations(Violation.PING), is(1L));
+        assertThat(policy.getViolations(Violation.RST_STREAM), is(0L));
+        assertThat(policy.getGoAways(), is(1L));
+        assertThat(policy.getDelayed(), is(0L));
+
+        policy.statsReset();
+        assertThat(policy.getViolations(Violation.PING), is(0L));
+        assertThat(policy.getGoAways(), is(0L));
+    }
+
+    @Test
+    public void testPerViolationThresholds()
+    {
+        Http2AbusePolicy policy = new Http2AbusePolicy(100);
+        policy.setMaxEvents(Violation.RST_STREAM, 2);
+        Parser parser = newParser(policy);
+
+        // Pings are within their threshold.
+        assertThat(parseUntilFailure(parser, PING, 50), is(50));
+        assertThat(failure.get(), nullValue());
+
+        // Resets exceed theirs.
+        assertThat(parseUntilFailure(parser, RESET, 50), is(3));
+        assertThat(failure.get(), is(ErrorCode.ENHANCE_YOUR_CALM_ERROR.code));
+        assertThat(policy.getViolations().get(Violation.RST_STREAM.name()), is(1L));
+        assertThat(policy.getViolations().get(Violation.PING.name()), is(0L));
+    }
+
+    @Test
+    public void testEscalation()
+    {
+        Http2AbusePolicy policy = new Http2AbusePolicy(1);
+        policy.setWindow(Duration.ofMinutes(1));
+        policy.setDelay(Duration.ofMillis(1));
+        policy.setWeight(Violation.RST_STREAM, 2);
+        policy.setGoAwayScore(4);
+        policy.setBlockScore(4);
+        List<Integer> scores = new CopyOnWriteArrayList<>();
+        AtomicReference<EndPoint> blocked = new AtomicReference<>();
+        policy.addListener(new Http2AbusePolicy.Listener()
+        {
+            @Override
+            public void onViolation(EndPoint endPoint, Violation violation, int score)
+            {
+                scores.add(score);
+            }
+
+            @Override
+            public void onBlock(EndPoint endPoint, Violation violation)
+            {
+                blocked.set(endPoint);
+            }
+        });
+        Parser parser = newParser(policy);
+
+        // The first ping is allowed, the next two are delayed.
+        assertThat(parseUntilFailure(parser, PING, 3), is(3));
+        assertThat(failure.get(), nullValue());
+        assertThat(policy.getDelayed(), is(2L));
+        assertThat(blocked.get(), nullValue());
+
+        // The first reset is allowed, the second reaches the GOAWAY and block scores.
+        assertThat(parseUntilFailure(parser, RESET, 10), is(2));
+        assertThat(failure.get(), is(ErrorCode.ENHANCE_YOUR_CALM_ERROR.code));
+        assertThat(scores, contains(1, 2, 4));
+        assertThat(blocked.get(), sameInstance(endPoint));
+        assertThat(policy.getBlocks(), is(1L));
+        assertThat(policy.getGoAways(), is(1L));
+    }
+}
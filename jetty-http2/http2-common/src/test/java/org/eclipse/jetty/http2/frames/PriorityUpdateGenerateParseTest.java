@@ -0,0 +1,145 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.frames;
+
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.List;
+
+import org.eclipse.jetty.http2.ErrorCode;
+import org.eclipse.jetty.http2.generator.HeaderGenerator;
+import org.eclipse.jetty.http2.generator.PriorityUpdateGenerator;
+import org.eclipse.jetty.http2.parser.Parser;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.MappedByteBufferPool;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class PriorityUpdateGenerateParseTest
+{
+    private final ByteBufferPool byteBufferPool = new MappedByteBufferPool();
+
+    @Test
+    public void testGenerateParse() throws Exception
+    {
+        PriorityUpdateGenerator generator = new PriorityUpdateGenerator(new HeaderGenerator());
+
+        final List<PriorityUpdateFrame> frames = new ArrayList<>();
+        Parser parser = new Parser(byteBufferPool, 8192);
+        parser.init(new Parser.Listener.Adapter()
+        {
+            @Override
+            public void onPriorityUpdate(PriorityUpdateFrame frame)
+            {
+                frames.add(frame);
+            }
+        });
+
+        int prioritizedStreamId = 13;
+        String priority = "u=1, i";
+
+        // Iterate a few times to be sure generator and parser are properly reset.
+        for (int i = 0; i < 2; ++i)
+        {
+            ByteBufferPool.Lease lease = new ByteBufferPool.Lease(byteBufferPool);
+            generator.generatePriorityUpdate(lease, prioritizedStreamId, priority);
+
+            frames.clear();
+            for (ByteBuffer buffer : lease.getByteBuffers())
+            {
+                while (buffer.hasRemaining())
+                {
+                    parser.parse(buffer);
+                }
+            }
+        }
+
+        assertEquals(1, frames.size());
+        PriorityUpdateFrame frame = frames.get(0);
+        assertEquals(0, frame.getStreamId());
+        assertEquals(prioritizedStreamId, frame.getPrioritizedStreamId());
+        assertEquals(priority, frame.getPriority());
+    }
+
+    @Test
+    public void testGenerateParseOneByteAtATime() throws Exception
+    {
+        PriorityUpdateGenerator generator = new PriorityUpdateGenerator(new HeaderGenerator());
+
+        final List<PriorityUpdateFrame> frames = new ArrayList<>();
+        Parser parser = new Parser(byteBufferPool, 8192);
+        parser.init(new Parser.Listener.Adapter()
+        {
+            @Override
+            public void onPriorityUpdate(PriorityUpdateFrame frame)
+            {
+                frames.add(frame);
+            }
+        });
+
+        int prioritizedStreamId = 17;
+        String priority = "u=5";
+
+        // Iterate a few times to be sure generator and parser are properly reset.
+        for (int i = 0; i < 2; ++i)
+        {
+            ByteBufferPool.Lease lease = new ByteBufferPool.Lease(byteBufferPool);
+            generator.generatePriorityUpdate(lease, prioritizedStreamId, priority);
+
+            frames.clear();
+            for (ByteBuffer buffer : lease.getByteBuffers())
+            {
+                while (buffer.hasRemaining())
+                {
+                    parser.parse(ByteBuffer.wrap(new byte[]{buffer.get()}));
+                }
+            }
+
+            assertEquals(1, frames.size());
+            PriorityUpdateFrame frame = frames.get(0);
+            assertEquals(prioritizedStreamId, frame.getPrioritizedStreamId());
+            assertEquals(priority, frame.getPriority());
+        }
+    }
+
+    @Test
+    public void testParseZeroPrioritizedStreamId() throws Exception
+    {
+        final List<Integer> errors = new ArrayList<>();
+        Parser parser = new Parser(byteBufferPool, 8192);
+        parser.init(new Parser.Listener.Adapter()
+        {
+            @Override
+            public void onConnectionFailure(int error, String reason)
+            {
+                errors.add(error);
+            }
+        });
+
+        ByteBuffer buffer = ByteBuffer.allocate(Frame.HEADER_LENGTH + 4);
+        // Length, type, flags, stream id.
+        buffer.put((byte)0).put((byte)0).put((byte)4);
+        buffer.put((byte)FrameType.PRIORITY_UPDATE.getType());
+        buffer.put((byte)0);
+        buffer.putInt(0);
+        // Prioritized stream id.
+        buffer.putInt(0);
+        buffer.flip();
+        parser.parse(buffer);
+
+        assertEquals(1, errors.size());
+        assertEquals(ErrorCode.PROTOCOL_ERROR.code, (int)errors.get(0));
+    }
+}
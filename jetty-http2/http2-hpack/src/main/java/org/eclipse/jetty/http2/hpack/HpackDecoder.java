@@ -37,12 +37,19 @@ public class HpackDecoder
     public static final Logger LOG = LoggerFactory.getLogger(HpackDecoder.class);
     public static final HttpField.LongValueHttpField CONTENT_LENGTH_0 =
         new HttpField.LongValueHttpField(HttpHeader.CONTENT_LENGTH, 0L);
+    private static final int COMPRESSION_RATIO_MIN_DECODED_OCTETS = 64 * 1024;
 
     private final HpackContext _context;
     private final MetaDataBuilder _builder;
     private final HuffmanDecoder _huffmanDecoder;
     private final NBitIntegerDecoder _integerDecoder;
     private int _maxTableCapacity;
+    private int _maxCompressionRatio;
+    private Listener _listener;
+    private boolean _compressionRatioExceeded;
+    private long _encodedOctets;
+    private long _decodedOctets;
+    private long _dynamicTableHits;
 
     /**
      * @param maxHeaderSize The maximum allowed size of a decoded headers block,
@@ -97,6 +104,79 @@ public class HpackDecoder
         _builder.setMaxSize(maxHeaderListSize);
     }
 
+    public int getMaxCompressionRatio()
+    {
+        return _maxCompressionRatio;
+    }
+
+    /**
+     * <p>Sets the maximum ratio between the decoded header bytes and the
+     * HPACK encoded bytes received by this decoder over its lifetime.</p>
+     * <p>Very large ratios are typical of decompression bombs, where small
+     * header blocks repeatedly reference large dynamic table entries.
+     * When the ratio is exceeded, the {@link Listener} is notified, so that
+     * the dynamic table can be disabled for the remote encoder.</p>
+     *
+     * @param maxCompressionRatio the max compression ratio, or {@code 0} to disable the check
+     */
+    public void setMaxCompressionRatio(int maxCompressionRatio)
+    {
+        _maxCompressionRatio = maxCompressionRatio;
+    }
+
+    public Listener getListener()
+    {
+        return _listener;
+    }
+
+    public void setListener(Listener listener)
+    {
+        _listener = listener;
+    }
+
+    /**
+     * @return whether the max compression ratio has been exceeded
+     * @see #setMaxCompressionRatio(int)
+     */
+    public boolean isCompressionRatioExceeded()
+    {
+        return _compressionRatioExceeded;
+    }
+
+    /**
+     * @return the number of HPACK encoded octets decoded
+     */
+    public long getEncodedOctets()
+    {
+        return _encodedOctets;
+    }
+
+    /**
+     * @return the number of header octets decoded, expressed as total
+     * of all name and value bytes, plus 32 bytes per field
+     */
+    public long getDecodedOctets()
+    {
+        return _decodedOctets;
+    }
+
+    /**
+     * @return the number of fields decoded from references to the dynamic table
+     */
+    public long getDynamicTableHits()
+    {
+        return _dynamicTableHits;
+    }
+
+    /**
+     * @return the ratio between the decoded header octets and the encoded octets
+     */
+    public double getCompressionRatio()
+    {
+        long encoded = _encodedOctets;
+        return encoded == 0 ? 0 : (double)_decodedOctets / encoded;
+    }
+
     public MetaData decode(ByteBuffer buffer) throws HpackException.SessionException, HpackException.StreamException
     {
         if (LOG.isDebugEnabled())
@@ -107,6 +187,7 @@ public class HpackDecoder
         if (maxSize > 0 && buffer.remaining() > maxSize)
             throw new HpackException.SessionException("Header fields size too large");
 
+        _encodedOctets += buffer.remaining();
         boolean emitted = false;
         while (buffer.hasRemaining())
         {
@@ -139,6 +220,7 @@ public class HpackDecoder
                         LOG.debug("decode Idx {}", entry);
                     // emit
                     emitted = true;
+                    ++_dynamicTableHits;
                     _builder.emit(entry.getHttpField());
                 }
             }
@@ -300,9 +382,31 @@ public class HpackDecoder
             }
         }
 
+        _decodedOctets += _builder.getSize();
+        checkCompressionRatio();
         return _builder.build();
     }
 
+    private void checkCompressionRatio()
+    {
+        int maxRatio = getMaxCompressionRatio();
+        if (maxRatio <= 0 || _compressionRatioExceeded)
+            return;
+        // Avoid false positives for few small header blocks
+        // that are mostly encoded with static table references.
+        if (_decodedOctets < COMPRESSION_RATIO_MIN_DECODED_OCTETS)
+            return;
+        if (_decodedOctets > _encodedOctets * maxRatio)
+        {
+            _compressionRatioExceeded = true;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Compression ratio {} exceeded max {} for {}", getCompressionRatio(), maxRatio, this);
+            Listener listener = getListener();
+            if (listener != null)
+                listener.onCompressionRatioExceeded(this);
+        }
+    }
+
     private int integerDecode(ByteBuffer buffer, int prefix) throws HpackException.CompressionException
     {
         try
@@ -365,4 +469,18 @@ public class HpackDecoder
     {
         return String.format("HpackDecoder@%x{%s}", hashCode(), _context);
     }
+
+    /**
+     * <p>A listener for {@link HpackDecoder} events.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Invoked when the max compression ratio is exceeded.</p>
+         *
+         * @param decoder the decoder that exceeded the max compression ratio
+         * @see HpackDecoder#setMaxCompressionRatio(int)
+         */
+        void onCompressionRatioExceeded(HpackDecoder decoder);
+    }
 }
@@ -79,6 +79,8 @@ public class HpackEncoder
     private static final PreEncodedHttpField C_SCHEME_HTTP = new PreEncodedHttpField(HttpHeader.C_SCHEME, "http");
     private static final PreEncodedHttpField C_SCHEME_HTTPS = new PreEncodedHttpField(HttpHeader.C_SCHEME, "https");
     private static final EnumMap<HttpMethod, PreEncodedHttpField> C_METHODS = new EnumMap<>(HttpMethod.class);
+    private static final int ADAPTIVE_WINDOW_BLOCKS = 32;
+    private static final int ADAPTIVE_MIN_TABLE_CAPACITY = 512;
 
     static
     {
@@ -99,6 +101,15 @@ public class HpackEncoder
     private int _maxHeaderListSize;
     private int _headerListSize;
     private boolean _validateEncoding = true;
+    private boolean _adaptiveTableCapacity;
+    private int _adaptedTableCapacity = -1;
+    private int _adaptiveBlocks;
+    private int _adaptiveHits;
+    private int _adaptiveInserts;
+    private long _encodedOctets;
+    private long _headerOctets;
+    private long _dynamicTableHits;
+    private long _dynamicTableInserts;
 
     public HpackEncoder()
     {
@@ -184,6 +195,68 @@ public class HpackEncoder
         _validateEncoding = validateEncoding;
     }
 
+    public boolean isAdaptiveTableCapacity()
+    {
+        return _adaptiveTableCapacity;
+    }
+
+    /**
+     * <p>Sets whether the capacity of the dynamic header table is adapted
+     * to the observed reuse of the entries in the dynamic table.</p>
+     * <p>When enabled, the capacity is periodically halved if entries are
+     * added to the dynamic table more often than they are referenced, and
+     * doubled (up to {@link #getTableCapacity()}) if entries are reused
+     * and the dynamic table is almost full.</p>
+     *
+     * @param adaptiveTableCapacity whether the dynamic header table capacity is adaptive
+     */
+    public void setAdaptiveTableCapacity(boolean adaptiveTableCapacity)
+    {
+        _adaptiveTableCapacity = adaptiveTableCapacity;
+    }
+
+    /**
+     * @return the number of HPACK encoded octets produced
+     */
+    public long getEncodedOctets()
+    {
+        return _encodedOctets;
+    }
+
+    /**
+     * @return the number of header octets encoded, expressed as total
+     * of all name and value bytes, plus 32 bytes per field
+     */
+    public long getHeaderOctets()
+    {
+        return _headerOctets;
+    }
+
+    /**
+     * @return the number of fields encoded as references to the dynamic table
+     */
+    public long getDynamicTableHits()
+    {
+        return _dynamicTableHits;
+    }
+
+    /**
+     * @return the number of fields added to the dynamic table
+     */
+    public long getDynamicTableInserts()
+    {
+        return _dynamicTableInserts;
+    }
+
+    /**
+     * @return the ratio between the header octets and the encoded octets
+     */
+    public double getCompressionRatio()
+    {
+        long encoded = _encodedOctets;
+        return encoded == 0 ? 0 : (double)_headerOctets / encoded;
+    }
+
     public void encode(ByteBuffer buffer, MetaData metadata) throws HpackException
     {
         try
@@ -208,7 +281,7 @@ public class HpackEncoder
             int pos = buffer.position();
 
             // If max table size changed, send the correspondent instruction.
-            int tableCapacity = getTableCapacity();
+            int tableCapacity = isAdaptiveTableCapacity() ? adaptTableCapacity() : getTableCapacity();
             if (tableCapacity != _context.getMaxDynamicTableSize())
                 encodeMaxDynamicTableSize(buffer, tableCapacity);
 
@@ -288,6 +361,9 @@ public class HpackEncoder
             if (maxHeaderListSize > 0 && _headerListSize > maxHeaderListSize)
                 throw new HpackException.SessionException("Header size %d > %d", _headerListSize, maxHeaderListSize);
 
+            _encodedOctets += buffer.position() - pos;
+            _headerOctets += _headerListSize;
+
             if (LOG.isDebugEnabled())
                 LOG.debug(String.format("CtxTbl[%x] encoded %d octets", _context.hashCode(), buffer.position() - pos));
         }
@@ -303,6 +379,35 @@ public class HpackEncoder
         }
     }
 
+    private int adaptTableCapacity()
+    {
+        int tableCapacity = getTableCapacity();
+        if (_adaptedTableCapacity < 0 || _adaptedTableCapacity > tableCapacity)
+            _adaptedTableCapacity = tableCapacity;
+
+        if (++_adaptiveBlocks >= ADAPTIVE_WINDOW_BLOCKS)
+        {
+            int capacity = _adaptedTableCapacity;
+            if (_adaptiveInserts > _adaptiveHits)
+            {
+                // Entries are not reused enough to be worth their memory.
+                capacity = Math.max(Math.min(tableCapacity, ADAPTIVE_MIN_TABLE_CAPACITY), capacity / 2);
+            }
+            else if (_adaptiveHits > 0 && _context.getDynamicTableSize() > capacity * 3 / 4)
+            {
+                // Entries are reused, but the table is almost full.
+                capacity = Math.min(tableCapacity, Math.max(ADAPTIVE_MIN_TABLE_CAPACITY, capacity * 2));
+            }
+            if (LOG.isDebugEnabled() && capacity != _adaptedTableCapacity)
+                LOG.debug("Adapted table capacity {}->{} hits={} inserts={}", _adaptedTableCapacity, capacity, _adaptiveHits, _adaptiveInserts);
+            _adaptedTableCapacity = capacity;
+            _adaptiveBlocks = 0;
+            _adaptiveHits = 0;
+            _adaptiveInserts = 0;
+        }
+        return _adaptedTableCapacity;
+    }
+
     public void encodeMaxDynamicTableSize(ByteBuffer buffer, int maxTableSize)
     {
         buffer.put((byte)0x20);
@@ -334,6 +439,8 @@ public class HpackEncoder
             else
             {
                 int index = _context.index(entry);
+                ++_dynamicTableHits;
+                ++_adaptiveHits;
                 buffer.put((byte)0x80);
                 NBitIntegerEncoder.encode(buffer, 7, index);
                 if (_debug)
@@ -440,8 +547,11 @@ public class HpackEncoder
             }
 
             // If we want the field referenced, then we add it to our table and reference set.
-            if (indexed)
-                _context.add(field);
+            if (indexed && _context.add(field) != null)
+            {
+                ++_dynamicTableInserts;
+                ++_adaptiveInserts;
+            }
         }
 
         if (_debug)
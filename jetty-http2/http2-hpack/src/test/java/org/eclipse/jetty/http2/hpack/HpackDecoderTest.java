@@ -15,14 +15,18 @@ package org.eclipse.jetty.http2.hpack;
 
 import java.nio.ByteBuffer;
 import java.util.Iterator;
+import java.util.concurrent.atomic.AtomicInteger;
 
 import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.HttpVersion;
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http2.hpack.HpackException.CompressionException;
 import org.eclipse.jetty.http2.hpack.HpackException.SessionException;
 import org.eclipse.jetty.http2.hpack.HpackException.StreamException;
+import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.StringUtil;
 import org.hamcrest.Matchers;
 import org.junit.jupiter.api.Test;
@@ -564,4 +568,32 @@ public class HpackDecoderTest
         StreamException ex = assertThrows(StreamException.class, () -> decoder.decode(buffer));
         assertThat(ex.getMessage(), Matchers.containsString("Illegal header"));
     }
+
+    @Test
+    public void testMaxCompressionRatio() throws Exception
+    {
+        HpackEncoder encoder = new HpackEncoder();
+        HpackDecoder decoder = new HpackDecoder(8192);
+        decoder.setMaxCompressionRatio(20);
+        AtomicInteger exceeded = new AtomicInteger();
+        decoder.setListener(d -> exceeded.incrementAndGet());
+
+        // A large field that is referenced from the dynamic table in every block.
+        HttpFields fields = HttpFields.build().add("x-big", "x".repeat(4000));
+        ByteBuffer buffer = BufferUtil.allocate(8192);
+        for (int i = 0; i < 64; i++)
+        {
+            BufferUtil.clearToFill(buffer);
+            encoder.encode(buffer, new MetaData(HttpVersion.HTTP_2, fields));
+            BufferUtil.flipToFlush(buffer, 0);
+            MetaData metaData = decoder.decode(buffer);
+            assertThat(metaData.getFields().get("x-big").length(), is(4000));
+        }
+
+        assertTrue(decoder.isCompressionRatioExceeded());
+        assertEquals(1, exceeded.get());
+        assertEquals(63, decoder.getDynamicTableHits());
+        assertThat(decoder.getCompressionRatio(), Matchers.greaterThan(20.0));
+        assertThat(decoder.getDecodedOctets(), is(64L * (5 + 4000 + 32)));
+    }
 }
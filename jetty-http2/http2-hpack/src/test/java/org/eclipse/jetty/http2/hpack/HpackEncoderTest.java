@@ -309,6 +309,39 @@ public class HpackEncoderTest
         assertThat(context.size(), Matchers.is(1));
     }
 
+    @Test
+    public void testAdaptiveTableCapacity() throws Exception
+    {
+        HpackEncoder encoder = newHpackEncoder(4096);
+        encoder.setAdaptiveTableCapacity(true);
+        HpackContext context = encoder.getHpackContext();
+        ByteBuffer buffer = BufferUtil.allocate(4096);
+
+        // Fields that are never reused shrink the table.
+        for (int i = 0; i < 32; i++)
+        {
+            BufferUtil.clearToFill(buffer);
+            encoder.encode(buffer, new MetaData(HttpVersion.HTTP_2, HttpFields.build().add("u" + i, "unique")));
+        }
+        assertThat(context.getMaxDynamicTableSize(), Matchers.is(2048));
+
+        // Fields that are reused and almost fill the table grow it.
+        HttpFields.Mutable fields = HttpFields.build();
+        for (int i = 0; i < 20; i++)
+        {
+            fields.add(String.format("n%02d", i), "x".repeat(60));
+        }
+        for (int i = 0; i < 32; i++)
+        {
+            BufferUtil.clearToFill(buffer);
+            encoder.encode(buffer, new MetaData(HttpVersion.HTTP_2, fields));
+        }
+        assertThat(context.getMaxDynamicTableSize(), Matchers.is(4096));
+
+        assertThat(encoder.getDynamicTableHits(), Matchers.greaterThan(encoder.getDynamicTableInserts()));
+        assertThat(encoder.getCompressionRatio(), Matchers.greaterThan(1.0));
+    }
+
     private static HpackEncoder newHpackEncoder(int tableCapacity)
     {
         HpackEncoder encoder = new HpackEncoder();
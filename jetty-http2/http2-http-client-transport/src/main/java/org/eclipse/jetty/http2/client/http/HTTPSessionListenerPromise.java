@@ -24,6 +24,7 @@ import org.eclipse.jetty.client.api.Connection;
 import org.eclipse.jetty.http2.HTTP2Session;
 import org.eclipse.jetty.http2.api.Session;
 import org.eclipse.jetty.http2.frames.GoAwayFrame;
+import org.eclipse.jetty.http2.frames.PingFrame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
 import org.eclipse.jetty.util.Promise;
 
@@ -81,6 +82,16 @@ class HTTPSessionListenerPromise extends Session.Listener.Adapter implements Pro
         return new HttpConnectionOverHTTP2(destination, session);
     }
 
+    @Override
+    public void onPing(Session session, PingFrame frame)
+    {
+        if (!frame.isReply())
+            return;
+        HttpConnectionOverHTTP2 connection = getConnection();
+        if (connection != null)
+            connection.onPingReply(frame);
+    }
+
     @Override
     public void onGoAway(Session session, GoAwayFrame frame)
     {
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http2.client.http;
 
+import java.net.SocketAddress;
 import java.nio.channels.AsynchronousCloseException;
 import java.util.Iterator;
 import java.util.List;
@@ -23,6 +24,7 @@ import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentLinkedQueue;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicInteger;
+import javax.net.ssl.SSLSession;
 
 import org.eclipse.jetty.client.ConnectionPool;
 import org.eclipse.jetty.client.HttpChannel;
@@ -41,7 +43,11 @@ import org.eclipse.jetty.http2.HTTP2Session;
 import org.eclipse.jetty.http2.api.Session;
 import org.eclipse.jetty.http2.api.Stream;
 import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
 import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.statistic.SampleStatistic;
 import org.eclipse.jetty.util.thread.Sweeper;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
@@ -55,12 +61,17 @@ public class HttpConnectionOverHTTP2 extends HttpConnection implements Sweeper.S
     private final AtomicBoolean closed = new AtomicBoolean();
     private final AtomicInteger sweeps = new AtomicInteger();
     private final Session session;
+    private final PingMonitor.ConnectionPinger pinger;
     private boolean recycleHttpChannels = true;
 
     public HttpConnectionOverHTTP2(HttpDestination destination, Session session)
     {
         super(destination);
         this.session = session;
+        PingMonitor pingMonitor = destination.getHttpClient().getBean(PingMonitor.class);
+        this.pinger = pingMonitor == null ? null : pingMonitor.newConnectionPinger(this);
+        if (pinger != null)
+            pinger.start();
     }
 
     public Session getSession()
@@ -68,6 +79,25 @@ public class HttpConnectionOverHTTP2 extends HttpConnection implements Sweeper.S
         return session;
     }
 
+    /**
+     * @return the {@code PING} round trip times in nanoseconds of this connection,
+     * or null if this connection is not monitored by a {@link PingMonitor}
+     */
+    public SampleStatistic getPingStatistics()
+    {
+        return pinger == null ? null : pinger.getStatistics();
+    }
+
+    boolean isIdle()
+    {
+        return activeChannels.isEmpty();
+    }
+
+    boolean onPingReply(PingFrame frame)
+    {
+        return pinger != null && pinger.onPingReply(frame);
+    }
+
     public boolean isRecycleHttpChannels()
     {
         return recycleHttpChannels;
@@ -209,6 +239,8 @@ public class HttpConnectionOverHTTP2 extends HttpConnection implements Sweeper.S
     {
         if (closed.compareAndSet(false, true))
         {
+            if (pinger != null)
+                pinger.stop();
             getHttpDestination().remove(this);
 
             abort(failure);
@@ -230,6 +262,33 @@ public class HttpConnectionOverHTTP2 extends HttpConnection implements Sweeper.S
         return closed.get();
     }
 
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return session.getLocalSocketAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return session.getRemoteSocketAddress();
+    }
+
+    @Override
+    public String getProtocol()
+    {
+        return getHttpDestination().isSecure() ? "h2" : "h2c";
+    }
+
+    @Override
+    public SSLSession getSSLSession()
+    {
+        EndPoint endPoint = ((HTTP2Session)session).getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+            return ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getSSLEngine().getSession();
+        return null;
+    }
+
     private void abort(Throwable failure)
     {
         for (HttpChannel channel : activeChannels)
@@ -0,0 +1,362 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.client.http;
+
+import java.util.EventListener;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.statistic.SampleStatistic;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Monitors the liveness and the latency of HTTP/2 client connections
+ * by periodically sending {@code PING} frames on idle connections.</p>
+ * <p>When an instance of this class is added as a bean to {@code HttpClient},
+ * every HTTP/2 connection sends a {@code PING} frame after it has been idle,
+ * that is without active requests, for the {@link #getPingInterval() ping interval}.
+ * The round trip time of the {@code PING} is recorded both per connection,
+ * see {@link HttpConnectionOverHTTP2#getPingStatistics()}, and in aggregate.
+ * Connections whose {@code PING} is not acknowledged within the
+ * {@link #getPingTimeout() ping timeout}, or whose round trip time exceeds
+ * the {@link #getMaxRoundTripTime() max round trip time}, are evicted
+ * from the connection pool and closed.</p>
+ * <p>Applications may register {@link Listener}s to be notified of the
+ * round trip times and of the evictions, for example to alert on backend
+ * latency degradation.</p>
+ * <p>Note that periodic {@code PING}s prevent idle connections to be closed
+ * because of the idle timeout, so that connections are kept alive.</p>
+ */
+@ManagedObject("Monitors HTTP/2 client connections via PING frames")
+public class PingMonitor
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PingMonitor.class);
+
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
+    private final SampleStatistic roundTripTimes = new SampleStatistic();
+    private final LongAdder pingTimeouts = new LongAdder();
+    private final LongAdder evictions = new LongAdder();
+    private long pingInterval = 15000;
+    private long pingTimeout = 5000;
+    private long maxRoundTripTime;
+
+    /**
+     * @return the idle time in milliseconds after which a connection is pinged
+     */
+    @ManagedAttribute("The idle time in milliseconds after which a connection is pinged")
+    public long getPingInterval()
+    {
+        return pingInterval;
+    }
+
+    /**
+     * @param pingInterval the idle time in milliseconds after which a connection is pinged
+     */
+    public void setPingInterval(long pingInterval)
+    {
+        if (pingInterval <= 0)
+            throw new IllegalArgumentException("Invalid ping interval " + pingInterval);
+        this.pingInterval = pingInterval;
+    }
+
+    /**
+     * @return the time in milliseconds to wait for the {@code PING} reply before evicting the connection
+     */
+    @ManagedAttribute("The time in milliseconds to wait for the PING reply before evicting the connection")
+    public long getPingTimeout()
+    {
+        return pingTimeout;
+    }
+
+    /**
+     * @param pingTimeout the time in milliseconds to wait for the {@code PING} reply before evicting the connection
+     */
+    public void setPingTimeout(long pingTimeout)
+    {
+        if (pingTimeout <= 0)
+            throw new IllegalArgumentException("Invalid ping timeout " + pingTimeout);
+        this.pingTimeout = pingTimeout;
+    }
+
+    /**
+     * @return the max round trip time in milliseconds above which the connection is evicted, or 0 for no limit
+     */
+    @ManagedAttribute("The max round trip time in milliseconds above which the connection is evicted")
+    public long getMaxRoundTripTime()
+    {
+        return maxRoundTripTime;
+    }
+
+    /**
+     * @param maxRoundTripTime the max round trip time in milliseconds above which the connection is evicted,
+     * or 0 for no limit
+     */
+    public void setMaxRoundTripTime(long maxRoundTripTime)
+    {
+        this.maxRoundTripTime = maxRoundTripTime;
+    }
+
+    public void addListener(Listener listener)
+    {
+        listeners.add(Objects.requireNonNull(listener));
+    }
+
+    public boolean removeListener(Listener listener)
+    {
+        return listeners.remove(listener);
+    }
+
+    @ManagedAttribute("The number of PING round trips")
+    public long getRoundTrips()
+    {
+        return roundTripTimes.getCount();
+    }
+
+    @ManagedAttribute("The mean PING round trip time in microseconds")
+    public double getRoundTripTimeMean()
+    {
+        return roundTripTimes.getMean() / 1000;
+    }
+
+    @ManagedAttribute("The max PING round trip time in microseconds")
+    public long getRoundTripTimeMax()
+    {
+        return TimeUnit.NANOSECONDS.toMicros(roundTripTimes.getMax());
+    }
+
+    @ManagedAttribute("The number of PINGs that were not acknowledged in time")
+    public long getPingTimeouts()
+    {
+        return pingTimeouts.sum();
+    }
+
+    @ManagedAttribute("The number of evicted connections")
+    public long getEvictions()
+    {
+        return evictions.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void reset()
+    {
+        roundTripTimes.reset();
+        pingTimeouts.reset();
+        evictions.reset();
+    }
+
+    ConnectionPinger newConnectionPinger(HttpConnectionOverHTTP2 connection)
+    {
+        return new ConnectionPinger(connection);
+    }
+
+    private void notifyRoundTrip(HttpConnectionOverHTTP2 connection, long roundTripTime)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onRoundTrip(connection, roundTripTime);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyEvicted(HttpConnectionOverHTTP2 connection, Throwable reason)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onEvicted(connection, reason);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[interval=%d,timeout=%d,maxRTT=%d]", getClass().getSimpleName(), hashCode(), getPingInterval(), getPingTimeout(), getMaxRoundTripTime());
+    }
+
+    /**
+     * <p>Sends the {@code PING}s of a connection and tracks its round trip times.</p>
+     */
+    class ConnectionPinger
+    {
+        private final AutoLock lock = new AutoLock();
+        private final SampleStatistic statistics = new SampleStatistic();
+        private final AtomicLong pending = new AtomicLong();
+        private final HttpConnectionOverHTTP2 connection;
+        private final Scheduler scheduler;
+        private Scheduler.Task task;
+        private boolean stopped;
+
+        private ConnectionPinger(HttpConnectionOverHTTP2 connection)
+        {
+            this.connection = connection;
+            this.scheduler = connection.getHttpClient().getScheduler();
+        }
+
+        SampleStatistic getStatistics()
+        {
+            return statistics;
+        }
+
+        void start()
+        {
+            schedule(this::ping, getPingInterval());
+        }
+
+        void stop()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                stopped = true;
+                if (task != null)
+                    task.cancel();
+                task = null;
+            }
+        }
+
+        private void schedule(Runnable action, long delay)
+        {
+            try (AutoLock l = lock.lock())
+            {
+                if (stopped)
+                    return;
+                task = scheduler.schedule(action, delay, TimeUnit.MILLISECONDS);
+            }
+        }
+
+        private void ping()
+        {
+            if (!connection.isIdle())
+            {
+                // Only idle connections are pinged.
+                schedule(this::ping, getPingInterval());
+                return;
+            }
+            long payload = NanoTime.now();
+            pending.set(payload);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Sending PING on {}", connection);
+            schedule(() -> onTimeout(payload), getPingTimeout());
+            connection.getSession().ping(new PingFrame(payload, false), Callback.from(() -> {}, this::evict));
+        }
+
+        /**
+         * @param frame the {@code PING} reply
+         * @return whether the reply belongs to a {@code PING} sent by this pinger
+         */
+        boolean onPingReply(PingFrame frame)
+        {
+            long payload = frame.getPayloadAsLong();
+            if (!pending.compareAndSet(payload, 0))
+                return false;
+
+            try (AutoLock l = lock.lock())
+            {
+                if (task != null)
+                    task.cancel();
+                task = null;
+            }
+
+            long roundTripTime = NanoTime.since(payload);
+            statistics.record(roundTripTime);
+            roundTripTimes.record(roundTripTime);
+            if (LOG.isDebugEnabled())
+                LOG.debug("PING round trip {} ns on {}", roundTripTime, connection);
+            notifyRoundTrip(connection, roundTripTime);
+
+            long maxRoundTripTime = getMaxRoundTripTime();
+            if (maxRoundTripTime > 0 && roundTripTime > TimeUnit.MILLISECONDS.toNanos(maxRoundTripTime))
+                evict(new TimeoutException("PING round trip time exceeded: " + TimeUnit.NANOSECONDS.toMillis(roundTripTime) + " ms > " + maxRoundTripTime + " ms"));
+            else
+                schedule(this::ping, getPingInterval());
+            return true;
+        }
+
+        private void onTimeout(long payload)
+        {
+            if (!pending.compareAndSet(payload, 0))
+                return;
+            pingTimeouts.increment();
+            evict(new TimeoutException("PING timeout expired: " + getPingTimeout() + " ms"));
+        }
+
+        private void evict(Throwable reason)
+        {
+            try (AutoLock l = lock.lock())
+            {
+                if (stopped)
+                    return;
+            }
+            stop();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Evicting {}", connection, reason);
+            evictions.increment();
+            notifyEvicted(connection, reason);
+            connection.close(reason);
+        }
+    }
+
+    /**
+     * <p>A listener of the {@code PING} events of the monitored connections.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when a {@code PING} reply is received.</p>
+         *
+         * @param connection the connection
+         * @param roundTripTime the round trip time in nanoseconds
+         */
+        public default void onRoundTrip(HttpConnectionOverHTTP2 connection, long roundTripTime)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a connection is evicted, because its
+         * {@code PING} was not acknowledged in time, or its round trip time
+         * exceeded the max round trip time, or the {@code PING} could not be sent.</p>
+         *
+         * @param connection the evicted connection
+         * @param reason the reason of the eviction
+         */
+        public default void onEvicted(HttpConnectionOverHTTP2 connection, Throwable reason)
+        {
+        }
+    }
+}
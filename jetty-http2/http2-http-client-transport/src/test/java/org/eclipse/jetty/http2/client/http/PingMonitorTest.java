@@ -0,0 +1,179 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http2.client.http;
+
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpDestination;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http2.api.Session;
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.http2.server.HTTP2ServerConnectionFactory;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class PingMonitorTest extends AbstractTest
+{
+    @Test
+    public void testRoundTripTimeIsRecorded() throws Exception
+    {
+        start(new EmptyServerHandler());
+        PingMonitor monitor = new PingMonitor();
+        monitor.setPingInterval(100);
+        AtomicReference<HttpConnectionOverHTTP2> connectionRef = new AtomicReference<>();
+        monitor.addListener(new PingMonitor.Listener()
+        {
+            @Override
+            public void onRoundTrip(HttpConnectionOverHTTP2 connection, long roundTripTime)
+            {
+                connectionRef.set(connection);
+            }
+        });
+        client.addBean(monitor);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        await().atMost(5, TimeUnit.SECONDS).until(() -> monitor.getRoundTrips() >= 1);
+        HttpConnectionOverHTTP2 connection = connectionRef.get();
+        assertThat(connection, notNullValue());
+        assertThat(connection.getPingStatistics().getCount(), greaterThanOrEqualTo(1L));
+        assertThat(monitor.getRoundTripTimeMax(), greaterThan(0L));
+        assertThat(monitor.getEvictions(), is(0L));
+        assertThat(connection.isClosed(), is(false));
+    }
+
+    @Test
+    public void testPingTimeoutEvictsConnection() throws Exception
+    {
+        // Drop the PING replies, so that the PING times out.
+        startWithSlowPingReplies(-1);
+        PingMonitor monitor = new PingMonitor();
+        monitor.setPingInterval(100);
+        monitor.setPingTimeout(200);
+        EvictionListener listener = new EvictionListener();
+        monitor.addListener(listener);
+        client.addBean(monitor);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        assertTrue(listener.latch.await(5, TimeUnit.SECONDS));
+        assertThat(listener.reason.get(), instanceOf(TimeoutException.class));
+        assertThat(monitor.getPingTimeouts(), is(1L));
+        assertThat(monitor.getEvictions(), is(1L));
+        await().atMost(5, TimeUnit.SECONDS).until(() -> listener.connection.get().isClosed());
+    }
+
+    @Test
+    public void testMaxRoundTripTimeEvictsConnection() throws Exception
+    {
+        startWithSlowPingReplies(500);
+        PingMonitor monitor = new PingMonitor();
+        monitor.setPingInterval(100);
+        monitor.setPingTimeout(5000);
+        monitor.setMaxRoundTripTime(200);
+        EvictionListener listener = new EvictionListener();
+        monitor.addListener(listener);
+        client.addBean(monitor);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        assertTrue(listener.latch.await(5, TimeUnit.SECONDS));
+        assertThat(listener.reason.get(), instanceOf(TimeoutException.class));
+        assertThat(monitor.getPingTimeouts(), is(0L));
+        assertThat(monitor.getRoundTrips(), is(1L));
+        assertThat(monitor.getEvictions(), is(1L));
+        await().atMost(5, TimeUnit.SECONDS).until(() -> listener.connection.get().isClosed());
+
+        // A new connection is opened for subsequent requests.
+        response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+    }
+
+    /**
+     * @param delay the delay in milliseconds of the PING replies, or a negative value to drop them
+     */
+    private void startWithSlowPingReplies(long delay) throws Exception
+    {
+        prepareServer(new HTTP2ServerConnectionFactory(new HttpConfiguration()));
+        server.setHandler(new EmptyServerHandler());
+        server.start();
+        prepareClient();
+        client = new HttpClient(new HttpClientTransportOverHTTP2(http2Client)
+        {
+            @Override
+            protected HttpConnectionOverHTTP2 newHttpConnection(HttpDestination destination, Session session)
+            {
+                return new HttpConnectionOverHTTP2(destination, session)
+                {
+                    @Override
+                    boolean onPingReply(PingFrame frame)
+                    {
+                        if (delay < 0)
+                            return false;
+                        try
+                        {
+                            Thread.sleep(delay);
+                        }
+                        catch (InterruptedException x)
+                        {
+                            throw new RuntimeException(x);
+                        }
+                        return super.onPingReply(frame);
+                    }
+                };
+            }
+        });
+        client.start();
+    }
+
+    private static class EvictionListener implements PingMonitor.Listener
+    {
+        private final CountDownLatch latch = new CountDownLatch(1);
+        private final AtomicReference<HttpConnectionOverHTTP2> connection = new AtomicReference<>();
+        private final AtomicReference<Throwable> reason = new AtomicReference<>();
+
+        @Override
+        public void onEvicted(HttpConnectionOverHTTP2 connection, Throwable reason)
+        {
+            this.connection.set(connection);
+            this.reason.set(reason);
+            latch.countDown();
+        }
+    }
+}
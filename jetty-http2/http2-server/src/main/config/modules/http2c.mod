@@ -29,7 +29,20 @@ etc/jetty-http2c.xml
 ## Specifies the maximum number of keys in all SETTINGS frames received by a session.
 # jetty.http2c.maxSettingsKeys=64
 
-## Specifies the maximum number of bad frames and pings per second,
+## Specifies the maximum number of bad frames, pings and resets per second,
 ## after which a session is closed to avoid denial of service attacks.
 # jetty.http2c.rateControl.maxEventsPerSecond=50
+
+## Specifies the maximum time, in milliseconds, a stream may be stalled
+## by flow control before being reset, to avoid slow-read attacks; 0 disables.
+# jetty.http2c.maxStreamStallTime=0
+
+## Specifies whether the HPACK encoder dynamic table capacity
+## adapts to the observed reuse of header fields.
+# jetty.http2c.adaptiveEncoderTableCapacity=false
+
+## Specifies the maximum ratio between decoded header bytes and HPACK bytes
+## received by a session, after which the dynamic table is disabled for
+## that session, to avoid decompression bomb attacks; 0 disables.
+# jetty.http2c.maxDecoderCompressionRatio=0
 # end::documentation[]
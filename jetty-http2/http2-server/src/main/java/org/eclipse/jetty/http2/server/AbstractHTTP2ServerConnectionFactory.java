@@ -27,12 +27,15 @@ import org.eclipse.jetty.http2.BufferingFlowControlStrategy;
 import org.eclipse.jetty.http2.FlowControlStrategy;
 import org.eclipse.jetty.http2.HTTP2Connection;
 import org.eclipse.jetty.http2.ISession;
+import org.eclipse.jetty.http2.StreamPrioritizer;
 import org.eclipse.jetty.http2.api.Session;
 import org.eclipse.jetty.http2.api.server.ServerSessionListener;
 import org.eclipse.jetty.http2.frames.Frame;
 import org.eclipse.jetty.http2.frames.SettingsFrame;
 import org.eclipse.jetty.http2.generator.Generator;
 import org.eclipse.jetty.http2.hpack.HpackContext;
+import org.eclipse.jetty.http2.hpack.HpackDecoder;
+import org.eclipse.jetty.http2.hpack.HpackEncoder;
 import org.eclipse.jetty.http2.parser.RateControl;
 import org.eclipse.jetty.http2.parser.ServerParser;
 import org.eclipse.jetty.http2.parser.WindowRateControl;
@@ -56,6 +59,8 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
     private final HttpConfiguration httpConfiguration;
     private int maxDecoderTableCapacity = HpackContext.DEFAULT_MAX_TABLE_CAPACITY;
     private int maxEncoderTableCapacity = HpackContext.DEFAULT_MAX_TABLE_CAPACITY;
+    private boolean adaptiveEncoderTableCapacity;
+    private int maxDecoderCompressionRatio;
     private int initialSessionRecvWindow = 1024 * 1024;
     private int initialStreamRecvWindow = 512 * 1024;
     private int maxConcurrentStreams = 128;
@@ -65,7 +70,9 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
     private boolean connectProtocolEnabled = true;
     private RateControl.Factory rateControlFactory = new WindowRateControl.Factory(50);
     private FlowControlStrategy.Factory flowControlStrategyFactory = () -> new BufferingFlowControlStrategy(0.5F);
+    private StreamPrioritizer streamPrioritizer = new StreamPrioritizer.Extensible();
     private long streamIdleTimeout;
+    private long maxStreamStallTime;
     private boolean useInputDirectByteBuffers;
     private boolean useOutputDirectByteBuffers;
 
@@ -138,6 +145,43 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
         setMaxDecoderTableCapacity(maxTableSize);
     }
 
+    @ManagedAttribute("Whether the HPACK encoder dynamic table capacity adapts to the header reuse")
+    public boolean isAdaptiveEncoderTableCapacity()
+    {
+        return adaptiveEncoderTableCapacity;
+    }
+
+    /**
+     * <p>Sets whether the HPACK encoder dynamic table capacity is adapted,
+     * up to the max encoder table capacity, to the observed header reuse.</p>
+     *
+     * @param adaptiveEncoderTableCapacity whether the HPACK encoder dynamic table capacity is adaptive
+     * @see HpackEncoder#setAdaptiveTableCapacity(boolean)
+     */
+    public void setAdaptiveEncoderTableCapacity(boolean adaptiveEncoderTableCapacity)
+    {
+        this.adaptiveEncoderTableCapacity = adaptiveEncoderTableCapacity;
+    }
+
+    @ManagedAttribute("The HPACK decoder max compression ratio before the dynamic table is disabled")
+    public int getMaxDecoderCompressionRatio()
+    {
+        return maxDecoderCompressionRatio;
+    }
+
+    /**
+     * <p>Sets the max ratio between the decoded header bytes and the HPACK bytes
+     * received by a session, after which the dynamic table is disabled for
+     * that session, to protect against decompression bombs.</p>
+     *
+     * @param maxDecoderCompressionRatio the max compression ratio, or {@code 0} to disable the check
+     * @see HpackDecoder#setMaxCompressionRatio(int)
+     */
+    public void setMaxDecoderCompressionRatio(int maxDecoderCompressionRatio)
+    {
+        this.maxDecoderCompressionRatio = maxDecoderCompressionRatio;
+    }
+
     @ManagedAttribute("The initial size of session's flow control receive window")
     public int getInitialSessionRecvWindow()
     {
@@ -212,6 +256,25 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
         this.streamIdleTimeout = streamIdleTimeout;
     }
 
+    @ManagedAttribute("The max time in milliseconds a stream may be stalled by flow control")
+    public long getMaxStreamStallTime()
+    {
+        return maxStreamStallTime;
+    }
+
+    /**
+     * <p>Sets the max time a stream may be stalled by HTTP/2 flow control
+     * before it is reset, to protect against clients that read slowly.</p>
+     *
+     * @param maxStreamStallTime the max stall time in milliseconds,
+     * or a value less than or equal to zero to disable slow stream detection
+     * @see org.eclipse.jetty.http2.HTTP2Session#setMaxStreamStallTime(long)
+     */
+    public void setMaxStreamStallTime(long maxStreamStallTime)
+    {
+        this.maxStreamStallTime = maxStreamStallTime;
+    }
+
     @Deprecated
     public int getMaxFrameLength()
     {
@@ -272,7 +335,31 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
      */
     public void setRateControlFactory(RateControl.Factory rateControlFactory)
     {
-        this.rateControlFactory = Objects.requireNonNull(rateControlFactory);
+        updateBean(this.rateControlFactory, Objects.requireNonNull(rateControlFactory));
+        this.rateControlFactory = rateControlFactory;
+    }
+
+    /**
+     * @return the strategy that orders the streams when writing DATA frames
+     */
+    @ManagedAttribute("The strategy that orders the streams when writing DATA frames")
+    public StreamPrioritizer getStreamPrioritizer()
+    {
+        return streamPrioritizer;
+    }
+
+    /**
+     * <p>Sets the strategy that orders the streams when writing DATA frames.</p>
+     * <p>By default, streams are ordered according to RFC 9218, using the
+     * stream priority specified by the {@code Priority} request header
+     * and by {@code PRIORITY_UPDATE} frames.</p>
+     *
+     * @param streamPrioritizer the strategy that orders the streams,
+     * or {@code null} to write DATA frames in the order they are queued
+     */
+    public void setStreamPrioritizer(StreamPrioritizer streamPrioritizer)
+    {
+        this.streamPrioritizer = streamPrioritizer;
     }
 
     @ManagedAttribute("Whether to use direct ByteBuffers for reading")
@@ -325,11 +412,13 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
         ServerSessionListener listener = newSessionListener(connector, endPoint);
 
         Generator generator = new Generator(connector.getByteBufferPool(), isUseOutputDirectByteBuffers(), getMaxHeaderBlockFragment());
+        generator.getHpackEncoder().setAdaptiveTableCapacity(isAdaptiveEncoderTableCapacity());
         FlowControlStrategy flowControl = getFlowControlStrategyFactory().newFlowControlStrategy();
 
         ServerParser parser = newServerParser(connector, getRateControlFactory().newRateControl(endPoint));
         parser.setMaxFrameSize(getMaxFrameSize());
         parser.setMaxSettingsKeys(getMaxSettingsKeys());
+        parser.getHpackDecoder().setMaxCompressionRatio(getMaxDecoderCompressionRatio());
 
         HTTP2ServerSession session = new HTTP2ServerSession(connector.getScheduler(), endPoint, parser, generator, listener, flowControl);
         session.setMaxLocalStreams(getMaxConcurrentStreams());
@@ -343,8 +432,10 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
         if (streamIdleTimeout == 0)
             streamIdleTimeout = endPoint.getIdleTimeout();
         session.setStreamIdleTimeout(streamIdleTimeout);
+        session.setMaxStreamStallTime(getMaxStreamStallTime());
         session.setInitialSessionRecvWindow(getInitialSessionRecvWindow());
         session.setWriteThreshold(getHttpConfiguration().getOutputBufferSize());
+        session.setStreamPrioritizer(getStreamPrioritizer());
         session.setConnectProtocolEnabled(isConnectProtocolEnabled());
 
         RetainableByteBufferPool retainableByteBufferPool = connector.getByteBufferPool().asRetainableByteBufferPool();
@@ -400,6 +491,12 @@ public abstract class AbstractHTTP2ServerConnectionFactory extends AbstractConne
             return sessions.size();
         }
 
+        @ManagedAttribute(value = "The number of HTTP/2 streams of all sessions", readonly = true)
+        public int getStreams()
+        {
+            return sessions.stream().mapToInt(session -> session.getStreams().size()).sum();
+        }
+
         @Override
         public CompletableFuture<Void> shutdown()
         {
@@ -13,13 +13,18 @@
 
 package org.eclipse.jetty.http2.server;
 
+import java.nio.ByteBuffer;
+
 import org.eclipse.jetty.http.BadMessageException;
 import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.MetaData.Request;
+import org.eclipse.jetty.http2.frames.PrefaceFrame;
 import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.server.ConnectionFactory;
 import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.DetectorConnectionFactory;
 import org.eclipse.jetty.server.HttpConfiguration;
 import org.eclipse.jetty.server.HttpConnectionFactory;
 import org.eclipse.jetty.util.annotation.Name;
@@ -38,8 +43,17 @@ import org.slf4j.LoggerFactory;
  * is used to trigger a switch to an HTTP2 connection.    This approach
  * allows a single port to accept either HTTP/1 or HTTP/2 direct
  * connections.
+ * </p>
+ * <p>This factory is also a {@link ConnectionFactory.Detecting} that recognizes
+ * the HTTP/2 client preface, so that it can be combined with other detecting
+ * factories in a {@link DetectorConnectionFactory}, for example after the
+ * PROXY protocol, to detect HTTP/2 prior-knowledge connections.</p>
+ * <p>The standard HTTP/1.1 {@code Upgrade: h2c} mechanism is also supported,
+ * including for requests with content, which is forwarded to the upgraded
+ * stream, provided that it has a {@code Content-Length} that fits the stream
+ * flow control window.</p>
  */
-public class HTTP2CServerConnectionFactory extends HTTP2ServerConnectionFactory implements ConnectionFactory.Upgrading
+public class HTTP2CServerConnectionFactory extends HTTP2ServerConnectionFactory implements ConnectionFactory.Upgrading, ConnectionFactory.Detecting
 {
     private static final Logger LOG = LoggerFactory.getLogger(HTTP2CServerConnectionFactory.class);
 
@@ -65,13 +79,30 @@ public class HTTP2CServerConnectionFactory extends HTTP2ServerConnectionFactory
         return false;
     }
 
+    @Override
+    public Detection detect(ByteBuffer buffer)
+    {
+        byte[] preface = PrefaceFrame.PREFACE_BYTES;
+        int length = Math.min(buffer.remaining(), preface.length);
+        for (int i = 0; i < length; ++i)
+        {
+            if (buffer.get(buffer.position() + i) != preface[i])
+                return Detection.NOT_RECOGNIZED;
+        }
+        return length == preface.length ? Detection.RECOGNIZED : Detection.NEED_MORE_BYTES;
+    }
+
     @Override
     public Connection upgradeConnection(Connector connector, EndPoint endPoint, Request request, HttpFields.Mutable response101) throws BadMessageException
     {
         if (LOG.isDebugEnabled())
             LOG.debug("{} upgrading {}{}{}", this, request, System.lineSeparator(), request.getFields());
 
-        if (request.getContentLength() > 0)
+        // Only upgrade requests whose content can be forwarded to the upgraded
+        // stream without exceeding its flow control window, and that is not chunked.
+        if (request.getFields().contains(HttpHeader.TRANSFER_ENCODING))
+            return null;
+        if (request.getContentLength() > getInitialStreamRecvWindow())
             return null;
 
         HTTP2ServerConnection connection = (HTTP2ServerConnection)newConnection(connector, endPoint);
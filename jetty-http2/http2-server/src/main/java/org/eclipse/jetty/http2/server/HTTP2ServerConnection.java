@@ -317,7 +317,9 @@ public class HTTP2ServerConnection extends HTTP2Connection implements ServerPars
             responseFields.put(HttpHeader.UPGRADE, "h2c");
             responseFields.put(HttpHeader.CONNECTION, "Upgrade");
 
-            ((HTTP2ServerSession)getSession()).standardUpgrade();
+            // The content of the upgrade request, if any, precedes the client preface.
+            long contentLength = request.getContentLength();
+            ((HTTP2ServerSession)getSession()).standardUpgrade(contentLength);
 
             // We fake that we received a client preface, so that we can send the
             // server preface as the first HTTP/2 frame as required by the spec.
@@ -326,7 +328,7 @@ public class HTTP2ServerConnection extends HTTP2Connection implements ServerPars
             // This is the settings from the HTTP2-Settings header.
             upgradeFrames.add(settingsFrame);
             // Remember the request to send a response.
-            upgradeFrames.add(new HeadersFrame(1, request, null, true));
+            upgradeFrames.add(new HeadersFrame(1, request, null, contentLength <= 0));
         }
         return true;
     }
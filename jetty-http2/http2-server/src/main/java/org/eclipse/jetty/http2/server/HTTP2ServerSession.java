@@ -202,4 +202,9 @@ public class HTTP2ServerSession extends HTTP2Session implements ServerParser.Lis
     {
         getParser().standardUpgrade();
     }
+
+    public void standardUpgrade(long contentLength)
+    {
+        getParser().standardUpgrade(contentLength);
+    }
 }
@@ -20,12 +20,14 @@ import java.util.concurrent.atomic.AtomicReference;
 import java.util.function.Consumer;
 
 import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HostPortHttpField;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpGenerator;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpHeaderValue;
 import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpURI;
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http.PreEncodedHttpField;
 import org.eclipse.jetty.http2.HTTP2Channel;
@@ -36,6 +38,7 @@ import org.eclipse.jetty.http2.frames.HeadersFrame;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.WriteFlusher;
 import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.HeaderPolicy;
 import org.eclipse.jetty.server.HttpChannel;
 import org.eclipse.jetty.server.HttpConfiguration;
 import org.eclipse.jetty.server.HttpInput;
@@ -66,6 +69,13 @@ public class HttpChannelOverHTTP2 extends HttpChannel implements Closeable, Writ
         return getHttpTransport().getStream();
     }
 
+    @Override
+    public long getStreamId()
+    {
+        IStream stream = getStream();
+        return stream == null ? -1 : stream.getId();
+    }
+
     @Override
     public boolean isUseOutputDirectByteBuffers()
     {
@@ -105,7 +115,7 @@ public class HttpChannelOverHTTP2 extends HttpChannel implements Closeable, Writ
     {
         try
         {
-            MetaData.Request request = (MetaData.Request)frame.getMetaData();
+            MetaData.Request request = applyHeaderPolicy((MetaData.Request)frame.getMetaData());
             HttpFields fields = request.getFields();
 
             _expect100Continue = fields.contains(HttpHeader.EXPECT, HttpHeaderValue.CONTINUE.asString());
@@ -166,6 +176,31 @@ public class HttpChannelOverHTTP2 extends HttpChannel implements Closeable, Writ
         }
     }
 
+    private MetaData.Request applyHeaderPolicy(MetaData.Request request)
+    {
+        HeaderPolicy headerPolicy = getHttpConfiguration().getHeaderPolicy();
+        if (headerPolicy == null)
+            return request;
+
+        HttpFields.Mutable fields = HttpFields.build(request.getFields().size());
+        for (HttpField field : request.getFields())
+        {
+            field = HeaderPolicy.apply(headerPolicy, field);
+            if (field != null)
+                fields.add(field);
+        }
+        headerPolicy.onHeaders(fields);
+
+        if (request instanceof MetaData.ConnectRequest)
+        {
+            HttpURI uri = request.getURI();
+            HostPortHttpField authority = new HostPortHttpField(uri.getHost(), uri.getPort());
+            return new MetaData.ConnectRequest(uri.getScheme(), authority, uri.getPathQuery(), fields, request.getProtocol());
+        }
+        // The policy cannot modify the Content-Length field, so the content length is unchanged.
+        return new MetaData.Request(request.getMethod(), request.getURI(), request.getHttpVersion(), fields, request.getContentLength());
+    }
+
     public Runnable onPushRequest(MetaData.Request request)
     {
         try
@@ -14,6 +14,7 @@
 package org.eclipse.jetty.http2.server;
 
 import java.io.BufferedReader;
+import java.io.IOException;
 import java.io.InputStream;
 import java.io.InputStreamReader;
 import java.io.OutputStream;
@@ -25,6 +26,8 @@ import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicLong;
 import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.HostPortHttpField;
 import org.eclipse.jetty.http.HttpFields;
@@ -42,9 +45,13 @@ import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.MappedByteBufferPool;
 import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.DetectorConnectionFactory;
 import org.eclipse.jetty.server.HttpConnection;
 import org.eclipse.jetty.server.HttpConnectionFactory;
+import org.eclipse.jetty.server.ProxyConnectionFactory;
+import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.Utf8StringBuilder;
@@ -216,6 +223,137 @@ public class HTTP2CServerTest extends AbstractServerTest
         }
     }
 
+    @Test
+    public void testHTTP11UpgradeWithContent() throws Exception
+    {
+        server.stop();
+        server.setHandler(new EchoHandler());
+        server.start();
+
+        try (Socket client = new Socket("localhost", connector.getLocalPort()))
+        {
+            OutputStream output = client.getOutputStream();
+            output.write((
+                "POST /one HTTP/1.1\r\n" +
+                    "Host: localhost\r\n" +
+                    "Connection: Upgrade, HTTP2-Settings\r\n" +
+                    "Upgrade: h2c\r\n" +
+                    "HTTP2-Settings: AAEAAEAAAAIAAAABAAMAAABkAAQBAAAAAAUAAEAA\r\n" +
+                    "Content-Length: 11\r\n" +
+                    "\r\n" +
+                    "hello world").getBytes(StandardCharsets.ISO_8859_1));
+            output.flush();
+
+            assertThat(readUpgradeResponse(client.getInputStream()), Matchers.startsWith("HTTP/1.1 101 "));
+
+            byteBufferPool = new MappedByteBufferPool();
+            AtomicReference<HeadersFrame> headersRef = new AtomicReference<>();
+            StringBuilder content = new StringBuilder();
+            CountDownLatch latch = new CountDownLatch(2);
+            Parser parser = new Parser(byteBufferPool, 8192);
+            parser.init(new Parser.Listener.Adapter()
+            {
+                @Override
+                public void onHeaders(HeadersFrame frame)
+                {
+                    headersRef.set(frame);
+                    latch.countDown();
+                }
+
+                @Override
+                public void onData(DataFrame frame)
+                {
+                    content.append(BufferUtil.toString(frame.getData()));
+                    if (frame.isEndStream())
+                        latch.countDown();
+                }
+            });
+
+            parseResponse(client, parser);
+
+            assertTrue(latch.await(5, TimeUnit.SECONDS));
+            MetaData.Response responseMetaData = (MetaData.Response)headersRef.get().getMetaData();
+            assertEquals(200, responseMetaData.getStatus());
+            // The content of the upgrade request is forwarded to the upgraded stream.
+            assertThat(content.toString(), containsString("content=hello world"));
+        }
+    }
+
+    @Test
+    public void testPriorKnowledgeDetectedAfterProxyProtocol() throws Exception
+    {
+        server.stop();
+        server.setHandler(new EchoHandler());
+        HttpConnectionFactory http = connector.getConnectionFactory(HttpConnectionFactory.class);
+        HTTP2CServerConnectionFactory h2c = connector.getConnectionFactory(HTTP2CServerConnectionFactory.class);
+        // Detect h2c prior knowledge, falling back to HTTP/1.1.
+        DetectorConnectionFactory h2cDetector = new DetectorConnectionFactory(h2c);
+        h2cDetector.setFallbackProtocol(http.getProtocol());
+        // Detect the optional PROXY protocol, followed by either h2c or HTTP/1.1.
+        DetectorConnectionFactory detector = new DetectorConnectionFactory(new ProxyConnectionFactory(h2cDetector.getProtocol()), h2c);
+        detector.setFallbackProtocol(http.getProtocol());
+        connector.clearConnectionFactories();
+        connector.addConnectionFactory(detector);
+        connector.addConnectionFactory(h2cDetector);
+        connector.addConnectionFactory(http);
+        connector.addConnectionFactory(h2c);
+        server.start();
+
+        // HTTP/1.1 without PROXY protocol.
+        try (Socket client = new Socket("localhost", connector.getLocalPort()))
+        {
+            client.getOutputStream().write("GET /one HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n".getBytes(StandardCharsets.ISO_8859_1));
+            String response = IO.toString(client.getInputStream());
+            assertThat(response, containsString("HTTP/1.1 200 OK"));
+            assertThat(response, containsString("protocol=HTTP/1.1"));
+        }
+
+        byteBufferPool = new MappedByteBufferPool();
+        generator = new Generator(byteBufferPool);
+        ByteBufferPool.Lease lease = new ByteBufferPool.Lease(byteBufferPool);
+        generator.control(lease, new PrefaceFrame());
+        generator.control(lease, new SettingsFrame(new HashMap<>(), false));
+        MetaData.Request metaData = new MetaData.Request("GET", HttpScheme.HTTP.asString(), new HostPortHttpField("localhost:" + connector.getLocalPort()), "/two", HttpVersion.HTTP_2, HttpFields.EMPTY, -1);
+        generator.control(lease, new HeadersFrame(1, metaData, null, true));
+
+        // h2c prior knowledge with and without PROXY protocol.
+        for (String proxy : new String[]{"", "PROXY TCP4 1.2.3.4 5.6.7.8 1111 2222\r\n"})
+        {
+            try (Socket client = new Socket("localhost", connector.getLocalPort()))
+            {
+                OutputStream output = client.getOutputStream();
+                output.write(proxy.getBytes(StandardCharsets.US_ASCII));
+                for (ByteBuffer buffer : lease.getByteBuffers())
+                {
+                    output.write(BufferUtil.toArray(buffer.slice()));
+                }
+                output.flush();
+
+                StringBuilder content = new StringBuilder();
+                CountDownLatch latch = new CountDownLatch(1);
+                Parser parser = new Parser(byteBufferPool, 8192);
+                parser.init(new Parser.Listener.Adapter()
+                {
+                    @Override
+                    public void onData(DataFrame frame)
+                    {
+                        content.append(BufferUtil.toString(frame.getData()));
+                        if (frame.isEndStream())
+                            latch.countDown();
+                    }
+                });
+
+                parseResponse(client, parser);
+
+                assertTrue(latch.await(5, TimeUnit.SECONDS));
+                assertThat(content.toString(), containsString("protocol=HTTP/2.0"));
+                assertThat(content.toString(), containsString("uri=/two"));
+                if (!proxy.isEmpty())
+                    assertThat(content.toString(), containsString("remote=1.2.3.4"));
+            }
+        }
+    }
+
     @Test
     public void testHTTP20Direct() throws Exception
     {
@@ -348,4 +486,37 @@ public class HTTP2CServerTest extends AbstractServerTest
         Thread.sleep(1000);
         assertThat(fills.get(), Matchers.lessThan(5L));
     }
+
+    private static String readUpgradeResponse(InputStream input) throws IOException
+    {
+        Utf8StringBuilder response = new Utf8StringBuilder();
+        int crlfs = 0;
+        while (crlfs < 4)
+        {
+            int read = input.read();
+            if (read < 0)
+                break;
+            if (read == '\r' || read == '\n')
+                ++crlfs;
+            else
+                crlfs = 0;
+            response.append((byte)read);
+        }
+        return response.toString();
+    }
+
+    private static class EchoHandler extends AbstractHandler
+    {
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            baseRequest.setHandled(true);
+            response.setContentType("text/plain");
+            String content = "protocol=" + request.getProtocol() + "\n";
+            content += "uri=" + request.getRequestURI() + "\n";
+            content += "remote=" + request.getRemoteAddr() + "\n";
+            content += "content=" + IO.toString(request.getInputStream()) + "\n";
+            response.getOutputStream().print(content);
+        }
+    }
 }
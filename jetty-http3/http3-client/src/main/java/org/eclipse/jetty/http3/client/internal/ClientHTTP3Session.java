@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.client.internal;
 
+import java.nio.ByteBuffer;
 import java.util.HashMap;
 import java.util.Map;
 import java.util.concurrent.CompletableFuture;
@@ -59,6 +60,9 @@ public class ClientHTTP3Session extends ClientProtocolSession
         session = new HTTP3SessionClient(this, listener, promise);
         addBean(session);
         session.setStreamIdleTimeout(configuration.getStreamIdleTimeout());
+        session.setMaxStreamStallTime(configuration.getMaxStreamStallTime());
+        session.setLocalDatagramsEnabled(configuration.isDatagramsEnabled());
+        session.setWebTransportEnabled(configuration.isWebTransportEnabled());
 
         if (LOG.isDebugEnabled())
             LOG.debug("initializing HTTP/3 streams");
@@ -76,6 +80,7 @@ public class ClientHTTP3Session extends ClientProtocolSession
         QuicStreamEndPoint decoderEndPoint = openInstructionEndPoint(decoderStreamId);
         InstructionFlusher decoderInstructionFlusher = new InstructionFlusher(quicSession, decoderEndPoint, DecoderStreamConnection.STREAM_TYPE);
         decoder = new QpackDecoder(new InstructionHandler(decoderInstructionFlusher));
+        decoder.setMaxCompressionRatio(configuration.getMaxDecoderCompressionRatio());
         addBean(decoder);
         if (LOG.isDebugEnabled())
             LOG.debug("created decoder stream #{} on {}", decoderStreamId, decoderEndPoint);
@@ -142,6 +147,18 @@ public class ClientHTTP3Session extends ClientProtocolSession
             }
             return v;
         });
+        settings.compute(SettingsFrame.H3_DATAGRAM, (k, v) ->
+        {
+            if (v == null && configuration.isDatagramsEnabled())
+                v = 1L;
+            return v;
+        });
+        settings.compute(SettingsFrame.ENABLE_WEBTRANSPORT, (k, v) ->
+        {
+            if (v == null && configuration.isWebTransportEnabled())
+                v = 1L;
+            return v;
+        });
 
         if (LOG.isDebugEnabled())
             LOG.debug("configuring local {} on {}", settings, this);
@@ -186,6 +203,14 @@ public class ClientHTTP3Session extends ClientProtocolSession
                 int maxBlockedStreams = value.intValue();
                 encoder.setMaxBlockedStreams(maxBlockedStreams);
             }
+            else if (key == SettingsFrame.ENABLE_CONNECT_PROTOCOL)
+            {
+                session.setConnectProtocolEnabled(value == 1);
+            }
+            else if (key == SettingsFrame.H3_DATAGRAM)
+            {
+                session.setRemoteDatagramsEnabled(value == 1);
+            }
         });
     }
 
@@ -233,6 +258,12 @@ public class ClientHTTP3Session extends ClientProtocolSession
         }
     }
 
+    @Override
+    protected void onDatagram(ByteBuffer datagram)
+    {
+        session.onDatagram(datagram);
+    }
+
     @Override
     protected boolean onIdleTimeout()
     {
@@ -63,6 +63,12 @@ public class HTTP3SessionClient extends HTTP3Session implements Session.Client
         return new HTTP3StreamClient(this, endPoint, local);
     }
 
+    @Override
+    protected StreamType getLocalStreamType(boolean bidirectional)
+    {
+        return bidirectional ? StreamType.CLIENT_BIDIRECTIONAL : StreamType.CLIENT_UNIDIRECTIONAL;
+    }
+
     @Override
     public void onHeaders(long streamId, HeadersFrame frame, boolean wasBlocked)
     {
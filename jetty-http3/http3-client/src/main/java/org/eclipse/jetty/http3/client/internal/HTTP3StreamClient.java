@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.client.internal;
 
+import java.nio.ByteBuffer;
 import java.util.EnumSet;
 import java.util.concurrent.TimeoutException;
 
@@ -127,6 +128,21 @@ public class HTTP3StreamClient extends HTTP3Stream implements  Stream.Client
         }
     }
 
+    @Override
+    protected void notifyDatagram(ByteBuffer payload)
+    {
+        Listener listener = getListener();
+        try
+        {
+            if (listener != null)
+                listener.onDatagram(this, payload);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
     @Override
     protected boolean notifyIdleTimeout(TimeoutException timeout)
     {
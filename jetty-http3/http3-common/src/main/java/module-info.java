@@ -28,7 +28,10 @@ module org.eclipse.jetty.http3.common
     exports org.eclipse.jetty.http3.internal to
         org.eclipse.jetty.http3.client,
         org.eclipse.jetty.http3.server,
-        org.eclipse.jetty.http3.http.client.transport;
+        org.eclipse.jetty.http3.http.client.transport,
+        org.eclipse.jetty.webtransport.common,
+        org.eclipse.jetty.webtransport.client,
+        org.eclipse.jetty.webtransport.server;
     exports org.eclipse.jetty.http3.internal.generator to
         org.eclipse.jetty.http3.client,
         org.eclipse.jetty.http3.server;
@@ -23,6 +23,7 @@ import org.eclipse.jetty.util.annotation.ManagedObject;
 public class HTTP3Configuration
 {
     private long streamIdleTimeout = 30000;
+    private long maxStreamStallTime;
     private int inputBufferSize = 2048;
     private int outputBufferSize = 2048;
     private boolean useInputDirectByteBuffers = true;
@@ -30,8 +31,12 @@ public class HTTP3Configuration
     private int maxBlockedStreams = 64;
     private int maxDecoderTableCapacity = 64 * 1024;
     private int maxEncoderTableCapacity = 64 * 1024;
+    private int maxDecoderCompressionRatio;
     private int maxRequestHeadersSize = 8 * 1024;
     private int maxResponseHeadersSize = 8 * 1024;
+    private boolean connectProtocolEnabled = true;
+    private boolean datagramsEnabled;
+    private boolean webTransportEnabled;
 
     @ManagedAttribute("The stream idle timeout in milliseconds")
     public long getStreamIdleTimeout()
@@ -51,6 +56,27 @@ public class HTTP3Configuration
         this.streamIdleTimeout = streamIdleTimeout;
     }
 
+    @ManagedAttribute("The max time in milliseconds a stream may be stalled by flow control")
+    public long getMaxStreamStallTime()
+    {
+        return maxStreamStallTime;
+    }
+
+    /**
+     * <p>Sets the max time in milliseconds a stream may be stalled by QUIC flow control,
+     * waiting for the other peer to read, before the stream is reset.</p>
+     * <p>The stall time is cumulative over the stream lifetime, to detect also
+     * peers that read slowly in order to keep the stream resources allocated.</p>
+     * <p>Negative values and zero mean that stalled streams are never reset.</p>
+     * <p>Default value is {@code 0}.</p>
+     *
+     * @param maxStreamStallTime the max stream stall time in milliseconds
+     */
+    public void setMaxStreamStallTime(long maxStreamStallTime)
+    {
+        this.maxStreamStallTime = maxStreamStallTime;
+    }
+
     @ManagedAttribute("The size of the network input buffer")
     public int getInputBufferSize()
     {
@@ -159,6 +185,27 @@ public class HTTP3Configuration
         this.maxEncoderTableCapacity = maxTableCapacity;
     }
 
+    @ManagedAttribute("The QPACK decoder max compression ratio")
+    public int getMaxDecoderCompressionRatio()
+    {
+        return maxDecoderCompressionRatio;
+    }
+
+    /**
+     * <p>Sets the max ratio between the decoded header bytes and the QPACK
+     * bytes received by a session, to protect against decompression bombs.</p>
+     * <p>The default value is {@code 0}, which disables the check.</p>
+     * <p>Since the QPACK decoder dynamic table capacity cannot be reduced
+     * once communicated to the remote QPACK encoder, exceeding this ratio
+     * closes the session.</p>
+     *
+     * @param maxDecoderCompressionRatio the QPACK decoder max compression ratio
+     */
+    public void setMaxDecoderCompressionRatio(int maxDecoderCompressionRatio)
+    {
+        this.maxDecoderCompressionRatio = maxDecoderCompressionRatio;
+    }
+
     @ManagedAttribute("The max number of QPACK blocked streams")
     public int getMaxBlockedStreams()
     {
@@ -221,4 +268,71 @@ public class HTTP3Configuration
     {
         this.maxResponseHeadersSize = maxResponseHeadersSize;
     }
+
+    @ManagedAttribute("Whether the extended CONNECT protocol is enabled")
+    public boolean isConnectProtocolEnabled()
+    {
+        return connectProtocolEnabled;
+    }
+
+    /**
+     * <p>Sets whether the extended CONNECT protocol specified by RFC 9220
+     * is enabled, for example to carry WebSocket over HTTP/3.</p>
+     * <p>The default value is {@code true}.</p>
+     * <p>This value is configured in the server, and then communicated
+     * to the client via the {@code SETTINGS_ENABLE_CONNECT_PROTOCOL}
+     * setting of the SETTINGS frame.</p>
+     *
+     * @param connectProtocolEnabled whether the extended CONNECT protocol is enabled
+     */
+    public void setConnectProtocolEnabled(boolean connectProtocolEnabled)
+    {
+        this.connectProtocolEnabled = connectProtocolEnabled;
+    }
+
+    @ManagedAttribute("Whether HTTP datagrams are enabled")
+    public boolean isDatagramsEnabled()
+    {
+        return datagramsEnabled;
+    }
+
+    /**
+     * <p>Sets whether HTTP datagrams specified by RFC 9297 are enabled.</p>
+     * <p>The default value is {@code false}.</p>
+     * <p>This value is communicated to the other peer via the
+     * {@code SETTINGS_H3_DATAGRAM} setting of the SETTINGS frame;
+     * HTTP datagrams can only be exchanged if both peers enable them,
+     * and if QUIC datagrams are enabled as well via
+     * {@code QuicConfiguration.setDatagramsEnabled(boolean)}.</p>
+     *
+     * @param datagramsEnabled whether HTTP datagrams are enabled
+     */
+    public void setDatagramsEnabled(boolean datagramsEnabled)
+    {
+        this.datagramsEnabled = datagramsEnabled;
+    }
+
+    @ManagedAttribute("Whether WebTransport is enabled")
+    public boolean isWebTransportEnabled()
+    {
+        return webTransportEnabled;
+    }
+
+    /**
+     * <p>Sets whether WebTransport over HTTP/3 is enabled.</p>
+     * <p>The default value is {@code false}.</p>
+     * <p>This value is communicated to the other peer via the
+     * {@code SETTINGS_ENABLE_WEBTRANSPORT} setting of the SETTINGS frame.
+     * WebTransport sessions are established via the extended CONNECT
+     * method, and typically use HTTP datagrams, so both the extended
+     * CONNECT protocol and HTTP datagrams should be enabled as well.</p>
+     *
+     * @param webTransportEnabled whether WebTransport is enabled
+     * @see #setConnectProtocolEnabled(boolean)
+     * @see #setDatagramsEnabled(boolean)
+     */
+    public void setWebTransportEnabled(boolean webTransportEnabled)
+    {
+        this.webTransportEnabled = webTransportEnabled;
+    }
 }
@@ -117,6 +117,21 @@ public interface Stream
      */
     public CompletableFuture<Stream> trailer(HeadersFrame frame);
 
+    /**
+     * <p>Sends the given payload as an unreliable HTTP datagram (RFC 9297)
+     * associated with this stream.</p>
+     * <p>HTTP datagrams can only be sent if they have been negotiated by
+     * both peers, otherwise the returned {@link CompletableFuture} is
+     * completed exceptionally.</p>
+     * <p>The returned {@link CompletableFuture} is completed when the datagram
+     * has been queued to be sent; the datagram may be lost, or received
+     * out of order with respect to other datagrams, by the other peer.</p>
+     *
+     * @param payload the HTTP datagram payload
+     * @return the {@link CompletableFuture} that gets notified when the datagram has been queued
+     */
+    public CompletableFuture<Stream> datagram(ByteBuffer payload);
+
     /**
      * <p>Abruptly terminates this stream with the given error.</p>
      *
@@ -125,6 +140,11 @@ public interface Stream
      */
     public void reset(long error, Throwable failure);
 
+    /**
+     * @return the statistics of this stream
+     */
+    public Statistics getStatistics();
+
     /**
      * <p>The client side version of {@link Stream}.</p>
      */
@@ -227,6 +247,17 @@ public interface Stream
             {
             }
 
+            /**
+             * <p>Callback method invoked when an HTTP datagram is received.</p>
+             *
+             * @param stream the stream
+             * @param payload the HTTP datagram payload
+             * @see Stream#datagram(ByteBuffer)
+             */
+            public default void onDatagram(Stream.Client stream, ByteBuffer payload)
+            {
+            }
+
             /**
              * <p>Callback method invoked when the stream idle timeout elapses.</p>
              *
@@ -342,6 +373,17 @@ public interface Stream
             {
             }
 
+            /**
+             * <p>Callback method invoked when an HTTP datagram is received.</p>
+             *
+             * @param stream the stream
+             * @param payload the HTTP datagram payload
+             * @see Stream#datagram(ByteBuffer)
+             */
+            public default void onDatagram(Stream.Server stream, ByteBuffer payload)
+            {
+            }
+
             /**
              * <p>Callback method invoked when the stream idle timeout elapses.</p>
              *
@@ -425,4 +467,52 @@ public interface Stream
             return String.format("%s[%s]", getClass().getSimpleName(), frame);
         }
     }
+
+    /**
+     * <p>The statistics of a {@link Stream}.</p>
+     * <p>The statistics are updated while the stream is active, and
+     * retain their last values after the stream is closed.</p>
+     */
+    public interface Statistics
+    {
+        /**
+         * @return the number of bytes of DATA frames sent
+         */
+        public long getBytesSent();
+
+        /**
+         * @return the number of bytes of DATA frames received
+         */
+        public long getBytesReceived();
+
+        /**
+         * @return the number of frames sent
+         */
+        public long getFramesSent();
+
+        /**
+         * @return the number of frames received
+         */
+        public long getFramesReceived();
+
+        /**
+         * @return the number of times the stream has been stalled by QUIC flow control
+         */
+        public long getFlowControlStalls();
+
+        /**
+         * @return the total time, in milliseconds, the stream has been stalled
+         * by QUIC flow control, including the current stall if the stream is stalled
+         */
+        public long getFlowControlStallTime();
+
+        /**
+         * <p>Returns the total time, in milliseconds, that frames of the stream
+         * waited to be written, queued behind frames of other streams.</p>
+         * <p>The time spent stalled by flow control is not included.</p>
+         *
+         * @return the total head-of-line blocking time in milliseconds
+         */
+        public long getHeadOfLineBlockingTime();
+    }
 }
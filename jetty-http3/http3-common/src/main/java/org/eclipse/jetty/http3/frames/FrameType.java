@@ -24,7 +24,8 @@ public enum FrameType
     SETTINGS(0x4),
     PUSH_PROMISE(0x5),
     GOAWAY(0x7),
-    MAX_PUSH_ID(0xD);
+    MAX_PUSH_ID(0xD),
+    WEBTRANSPORT_STREAM(0x41);
 
     public static FrameType from(long type)
     {
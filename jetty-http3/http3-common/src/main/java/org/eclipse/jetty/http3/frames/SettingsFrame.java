@@ -20,6 +20,9 @@ public class SettingsFrame extends Frame
     public static final long MAX_TABLE_CAPACITY = 0x01;
     public static final long MAX_FIELD_SECTION_SIZE = 0x06;
     public static final long MAX_BLOCKED_STREAMS = 0x07;
+    public static final long ENABLE_CONNECT_PROTOCOL = 0x08;
+    public static final long H3_DATAGRAM = 0x33;
+    public static final long ENABLE_WEBTRANSPORT = 0x2B603742L;
 
     public static boolean isReserved(long key)
     {
@@ -33,7 +33,10 @@ public enum HTTP3ErrorCode
     REQUEST_INCOMPLETE_ERROR(0x10D),
     HTTP_MESSAGE_ERROR(0x10E),
     HTTP_CONNECT_ERROR(0x10F),
-    VERSION_FALLBACK_ERROR(0x110);
+    VERSION_FALLBACK_ERROR(0x110),
+    DATAGRAM_ERROR(0x33),
+    WEBTRANSPORT_BUFFERED_STREAM_REJECTED(0x3994BD84L),
+    WEBTRANSPORT_SESSION_GONE(0x170D7B68L);
 
     private final long code;
 
@@ -15,6 +15,7 @@ package org.eclipse.jetty.http3.internal;
 
 import java.io.IOException;
 import java.net.SocketAddress;
+import java.nio.ByteBuffer;
 import java.nio.channels.ClosedChannelException;
 import java.util.Collection;
 import java.util.Iterator;
@@ -22,10 +23,12 @@ import java.util.List;
 import java.util.Map;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.AtomicLong;
 import java.util.function.Consumer;
+import java.util.function.Function;
 import java.util.function.Predicate;
 
 import org.eclipse.jetty.http.MetaData;
@@ -37,9 +40,12 @@ import org.eclipse.jetty.http3.frames.GoAwayFrame;
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.frames.SettingsFrame;
 import org.eclipse.jetty.http3.internal.parser.ParserListener;
+import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.CyclicTimeouts;
 import org.eclipse.jetty.quic.common.ProtocolSession;
+import org.eclipse.jetty.quic.common.QuicSession;
 import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
+import org.eclipse.jetty.quic.common.StreamType;
 import org.eclipse.jetty.util.Atomics;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
@@ -56,11 +62,18 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
     private final AutoLock lock = new AutoLock();
     private final AtomicLong lastStreamId = new AtomicLong(0);
     private final Map<Long, HTTP3Stream> streams = new ConcurrentHashMap<>();
+    private final Map<Long, Function<QuicStreamEndPoint, Connection>> webTransportSessions = new ConcurrentHashMap<>();
     private final ProtocolSession session;
     private final Session.Listener listener;
     private final AtomicInteger streamCount = new AtomicInteger();
     private final StreamTimeouts streamTimeouts;
+    private final StreamStallTimeouts streamStallTimeouts;
     private long streamIdleTimeout;
+    private long maxStreamStallTime;
+    private volatile boolean connectProtocolEnabled;
+    private volatile boolean localDatagramsEnabled;
+    private volatile boolean remoteDatagramsEnabled;
+    private volatile boolean webTransportEnabled;
     private CloseState closeState = CloseState.CLOSED;
     private GoAwayFrame goAwaySent;
     private GoAwayFrame goAwayRecv;
@@ -72,6 +85,7 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         this.session = session;
         this.listener = listener;
         this.streamTimeouts = new StreamTimeouts(session.getQuicSession().getScheduler());
+        this.streamStallTimeouts = new StreamStallTimeouts(session.getQuicSession().getScheduler());
     }
 
     public ProtocolSession getProtocolSession()
@@ -112,6 +126,52 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         return session.getMaxLocalStreams();
     }
 
+    /**
+     * @return whether the extended CONNECT protocol (RFC 9220) is enabled
+     * by the server side of this session
+     */
+    public boolean isConnectProtocolEnabled()
+    {
+        return connectProtocolEnabled;
+    }
+
+    public void setConnectProtocolEnabled(boolean connectProtocolEnabled)
+    {
+        this.connectProtocolEnabled = connectProtocolEnabled;
+    }
+
+    /**
+     * @return whether HTTP datagrams (RFC 9297) have been negotiated by both peers of this session
+     */
+    public boolean isDatagramsEnabled()
+    {
+        return localDatagramsEnabled && remoteDatagramsEnabled &&
+            getProtocolSession().getQuicSession().getMaxDatagramLength() >= 0;
+    }
+
+    public void setLocalDatagramsEnabled(boolean localDatagramsEnabled)
+    {
+        this.localDatagramsEnabled = localDatagramsEnabled;
+    }
+
+    public void setRemoteDatagramsEnabled(boolean remoteDatagramsEnabled)
+    {
+        this.remoteDatagramsEnabled = remoteDatagramsEnabled;
+    }
+
+    /**
+     * @return whether WebTransport is enabled by the local side of this session
+     */
+    public boolean isWebTransportEnabled()
+    {
+        return webTransportEnabled;
+    }
+
+    public void setWebTransportEnabled(boolean webTransportEnabled)
+    {
+        this.webTransportEnabled = webTransportEnabled;
+    }
+
     @Override
     public CompletableFuture<Void> goAway(boolean graceful)
     {
@@ -266,6 +326,21 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         streamTimeouts.schedule(stream);
     }
 
+    public long getMaxStreamStallTime()
+    {
+        return maxStreamStallTime;
+    }
+
+    /**
+     * @param maxStreamStallTime the max time in milliseconds a stream may be stalled
+     * by flow control before being reset, or a value less than or equal to zero to disable
+     * @see org.eclipse.jetty.http3.HTTP3Configuration#setMaxStreamStallTime(long)
+     */
+    public void setMaxStreamStallTime(long maxStreamStallTime)
+    {
+        this.maxStreamStallTime = maxStreamStallTime;
+    }
+
     protected HTTP3Stream createStream(QuicStreamEndPoint endPoint, Consumer<Throwable> fail)
     {
         long streamId = endPoint.getStreamId();
@@ -301,6 +376,8 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
             long idleTimeout = getStreamIdleTimeout();
             if (idleTimeout > 0)
                 stream.setIdleTimeout(idleTimeout);
+            if (getMaxStreamStallTime() > 0)
+                streamStallTimeouts.schedule(stream);
             if (!local)
                 updateLastStreamId(stream.getId());
             if (LOG.isDebugEnabled())
@@ -341,10 +418,144 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         }
     }
 
+    /**
+     * <p>Registers a WebTransport session, so that the streams that the remote peer
+     * opens for that session are linked to the {@link Connection}s created by
+     * the given factory.</p>
+     *
+     * @param sessionId the WebTransport session id, that is the id of its CONNECT stream
+     * @param factory the factory of {@link Connection}s for the session streams
+     * @see #removeWebTransportSession(long)
+     */
+    public void addWebTransportSession(long sessionId, Function<QuicStreamEndPoint, Connection> factory)
+    {
+        webTransportSessions.put(sessionId, factory);
+    }
+
+    /**
+     * @param sessionId the WebTransport session id to unregister
+     * @see #addWebTransportSession(long, Function)
+     */
+    public void removeWebTransportSession(long sessionId)
+    {
+        webTransportSessions.remove(sessionId);
+    }
+
+    /**
+     * <p>Opens a new local stream for a WebTransport session.</p>
+     * <p>The {@link Connection} created by the given factory is responsible
+     * to write the stream preface that links the stream to the session.</p>
+     *
+     * @param bidirectional whether the stream is bidirectional
+     * @param factory the factory of the {@link Connection} for the stream
+     * @return the stream {@link QuicStreamEndPoint}
+     */
+    public QuicStreamEndPoint newWebTransportStream(boolean bidirectional, Function<QuicStreamEndPoint, Connection> factory)
+    {
+        long streamId = session.getQuicSession().newStreamId(getLocalStreamType(bidirectional));
+        if (LOG.isDebugEnabled())
+            LOG.debug("new {} WebTransport stream #{} on {}", bidirectional ? "bidirectional" : "unidirectional", streamId, this);
+        return session.getOrCreateStreamEndPoint(streamId, endPoint ->
+        {
+            endPoint.setConnection(factory.apply(endPoint));
+            endPoint.opened();
+        });
+    }
+
+    /**
+     * @param bidirectional whether the stream is bidirectional
+     * @return the type of the streams initiated by this side of the session
+     */
+    protected abstract StreamType getLocalStreamType(boolean bidirectional);
+
+    @Override
+    public void onWebTransportStream(long streamId, long sessionId)
+    {
+        QuicStreamEndPoint endPoint = session.getStreamEndPoint(streamId);
+        Function<QuicStreamEndPoint, Connection> factory = webTransportSessions.get(sessionId);
+        if (LOG.isDebugEnabled())
+            LOG.debug("received WebTransport stream #{} for session #{} on {}", streamId, sessionId, this);
+        if (endPoint == null)
+            return;
+        if (factory == null)
+        {
+            // SPEC: streams for unknown sessions may be buffered, but here they are rejected.
+            endPoint.close(HTTP3ErrorCode.WEBTRANSPORT_BUFFERED_STREAM_REJECTED.code(), new IOException("unknown_webtransport_session"));
+            return;
+        }
+        endPoint.upgrade(factory.apply(endPoint));
+    }
+
     public abstract void writeControlFrame(Frame frame, Callback callback);
 
     public abstract void writeMessageFrame(long streamId, Frame frame, Callback callback);
 
+    /**
+     * <p>Sends the given payload as an HTTP datagram associated with the given request stream.</p>
+     *
+     * @param streamId the request stream id
+     * @param payload the HTTP datagram payload
+     * @return a future completed when the datagram has been queued to be sent
+     */
+    public CompletableFuture<Void> writeDatagram(long streamId, ByteBuffer payload)
+    {
+        if (!isDatagramsEnabled())
+            return CompletableFuture.failedFuture(new IllegalStateException("HTTP datagrams not negotiated"));
+        // SPEC: HTTP datagrams are prefixed with the quarter stream id.
+        long quarterStreamId = streamId >>> 2;
+        ByteBuffer datagram = ByteBuffer.allocate(VarLenInt.length(quarterStreamId) + payload.remaining());
+        VarLenInt.encode(datagram, quarterStreamId);
+        datagram.put(payload.slice()).flip();
+        QuicSession quicSession = getProtocolSession().getQuicSession();
+        int maxLength = quicSession.getMaxDatagramLength();
+        if (datagram.remaining() > maxLength)
+            return CompletableFuture.failedFuture(new IllegalArgumentException("datagram too large: " + datagram.remaining() + " > " + maxLength));
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("sending datagram of {} bytes for stream #{} on {}", payload.remaining(), streamId, this);
+            if (!quicSession.sendDatagram(datagram))
+                return CompletableFuture.failedFuture(new IllegalStateException("datagram send queue full"));
+            payload.position(payload.limit());
+            return CompletableFuture.completedFuture(null);
+        }
+        catch (IOException x)
+        {
+            return CompletableFuture.failedFuture(x);
+        }
+    }
+
+    public void onDatagram(ByteBuffer datagram)
+    {
+        if (!localDatagramsEnabled)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("dropping datagram, HTTP datagrams disabled on {}", this);
+            return;
+        }
+
+        AtomicLong quarterStreamId = new AtomicLong(-1);
+        if (!new VarLenInt().decode(datagram, quarterStreamId::set))
+        {
+            onSessionFailure(HTTP3ErrorCode.DATAGRAM_ERROR.code(), "invalid_datagram", new IllegalArgumentException("invalid datagram"));
+            return;
+        }
+
+        // SPEC: the quarter stream id identifies a client-initiated bidirectional stream.
+        if (quarterStreamId.get() >= 1L << 60)
+        {
+            onSessionFailure(HTTP3ErrorCode.ID_ERROR.code(), "invalid_datagram_stream_id", new IllegalArgumentException("invalid datagram stream id"));
+            return;
+        }
+        long streamId = quarterStreamId.get() << 2;
+        HTTP3Stream stream = getStream(streamId);
+        if (LOG.isDebugEnabled())
+            LOG.debug("received datagram of {} bytes for stream #{} on {}", datagram.remaining(), streamId, stream);
+        // SPEC: datagrams for unknown or closed streams are dropped.
+        if (stream != null)
+            stream.onDatagram(datagram);
+    }
+
     public Map<Long, Long> onPreface()
     {
         Map<Long, Long> settings = notifyPreface();
@@ -724,6 +935,7 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         if (LOG.isDebugEnabled())
             LOG.debug("terminating {}", this);
         streamTimeouts.destroy();
+        streamStallTimeouts.destroy();
         // Notify the shutdown completable.
         CompletableFuture<Void> shutdown;
         try (AutoLock ignored = lock.lock())
@@ -889,6 +1101,58 @@ public abstract class HTTP3Session extends ContainerLifeCycle implements Session
         NOT_CLOSED, LOCALLY_CLOSED, REMOTELY_CLOSED, CLOSING, CLOSED
     }
 
+    private class StreamStallTimeouts extends CyclicTimeouts<StreamStall>
+    {
+        private StreamStallTimeouts(Scheduler scheduler)
+        {
+            super(scheduler);
+        }
+
+        @Override
+        protected Iterator<StreamStall> iterator()
+        {
+            return streams.values().stream()
+                .map(StreamStall::new)
+                .iterator();
+        }
+
+        @Override
+        protected boolean onExpired(StreamStall stall)
+        {
+            // Streams that are not stalled are only checked periodically.
+            if (stall.stream.getEndPoint().isFlushStalled())
+                stall.stream.onStallExpired(getMaxStreamStallTime());
+            // The iterator returned from the method above does not support removal.
+            return false;
+        }
+
+        private void schedule(HTTP3Stream stream)
+        {
+            schedule(new StreamStall(stream));
+        }
+    }
+
+    private class StreamStall implements CyclicTimeouts.Expirable
+    {
+        private final HTTP3Stream stream;
+
+        private StreamStall(HTTP3Stream stream)
+        {
+            this.stream = stream;
+        }
+
+        @Override
+        public long getExpireNanoTime()
+        {
+            long maxStallTime = getMaxStreamStallTime();
+            if (maxStallTime <= 0)
+                return Long.MAX_VALUE;
+            // The QUIC stall events are not notified, so streams are polled
+            // at most when they could have exhausted their stall allowance.
+            return stream.getEndPoint().getFlushStallExpireNanoTime(TimeUnit.MILLISECONDS.toNanos(maxStallTime));
+        }
+    }
+
     private class StreamTimeouts extends CyclicTimeouts<HTTP3Stream>
     {
         private StreamTimeouts(Scheduler scheduler)
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.internal;
 
+import java.nio.ByteBuffer;
 import java.util.EnumSet;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.TimeUnit;
@@ -39,6 +40,7 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
     private final HTTP3Session session;
     private final QuicStreamEndPoint endPoint;
     private final boolean local;
+    private final HTTP3StreamStatistics statistics;
     private CloseState closeState = CloseState.NOT_CLOSED;
     private FrameState frameState = FrameState.INITIAL;
     private long idleTimeout;
@@ -50,6 +52,7 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
         this.session = session;
         this.endPoint = endPoint;
         this.local = local;
+        this.statistics = new HTTP3StreamStatistics(endPoint);
     }
 
     public QuicStreamEndPoint getEndPoint()
@@ -86,6 +89,12 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
         return local;
     }
 
+    @Override
+    public HTTP3StreamStatistics getStatistics()
+    {
+        return statistics;
+    }
+
     public long getIdleTimeout()
     {
         return idleTimeout;
@@ -149,7 +158,10 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
             HTTP3StreamConnection connection = (HTTP3StreamConnection)endPoint.getConnection();
             Data data = connection.readData();
             if (data != null)
+            {
+                statistics.onDataRead(data.getByteBuffer().remaining());
                 updateClose(data.isLast(), false);
+            }
             return data;
         }
         catch (Throwable x)
@@ -177,6 +189,12 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
         return write(frame);
     }
 
+    @Override
+    public CompletableFuture<Stream> datagram(ByteBuffer payload)
+    {
+        return session.writeDatagram(getId(), payload).thenApply(v -> this);
+    }
+
     public boolean hasDemand()
     {
         HTTP3StreamConnection connection = (HTTP3StreamConnection)endPoint.getConnection();
@@ -208,6 +226,14 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
 
     protected abstract void notifyTrailer(HeadersFrame frame);
 
+    public void onDatagram(ByteBuffer payload)
+    {
+        notIdle();
+        notifyDatagram(payload);
+    }
+
+    protected abstract void notifyDatagram(ByteBuffer payload);
+
     protected abstract boolean notifyIdleTimeout(TimeoutException timeout);
 
     public void onFailure(long error, Throwable failure)
@@ -223,6 +249,7 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
         if (allowed.contains(frameState))
         {
             frameState = target;
+            statistics.onFrameReceived();
             return true;
         }
         else
@@ -240,11 +267,29 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
     public Promise.Completable<Stream> writeFrame(Frame frame)
     {
         notIdle();
+        long dataBytes = frame instanceof DataFrame ? ((DataFrame)frame).getByteBuffer().remaining() : 0;
+        long queueNanoTime = NanoTime.now();
+        long queueStallNanos = endPoint.getFlushStallNanos();
         Promise.Completable<Stream> completable = new Promise.Completable<>();
-        session.writeMessageFrame(endPoint.getStreamId(), frame, Callback.from(Invocable.InvocationType.NON_BLOCKING, () -> completable.succeeded(this), completable::failed));
+        session.writeMessageFrame(endPoint.getStreamId(), frame, Callback.from(Invocable.InvocationType.NON_BLOCKING, () ->
+        {
+            // Exclude the time the frame was stalled by flow control.
+            long stallNanos = endPoint.getFlushStallNanos() - queueStallNanos;
+            statistics.onHeadOfLineBlocked(NanoTime.since(queueNanoTime) - stallNanos);
+            statistics.onFrameSent(dataBytes);
+            completable.succeeded(this);
+        }, completable::failed));
         return completable;
     }
 
+    void onStallExpired(long maxStallTime)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("flow control stall {} ms exceeded {} ms on {}", statistics.getFlowControlStallTime(), maxStallTime, this);
+        // The peer is not reading, free the resources held by this stream.
+        reset(HTTP3ErrorCode.EXCESSIVE_LOAD_ERROR.code(), new TimeoutException("flow control stall " + maxStallTime + " ms exceeded"));
+    }
+
     public boolean isClosed()
     {
         return closeState == CloseState.CLOSED;
@@ -304,12 +349,13 @@ public abstract class HTTP3Stream implements Stream, CyclicTimeouts.Expirable, A
     @Override
     public String toString()
     {
-        return String.format("%s@%x#%d[demand=%b,idle=%d,session=%s]",
+        return String.format("%s@%x#%d[demand=%b,idle=%d,stalled=%b,session=%s]",
             getClass().getSimpleName(),
             hashCode(),
             getId(),
             hasDemand(),
             NanoTime.millisSince(expireNanoTime),
+            endPoint.isFlushStalled(),
             getSession()
         );
     }
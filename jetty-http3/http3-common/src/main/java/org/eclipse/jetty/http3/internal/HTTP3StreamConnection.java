@@ -28,6 +28,7 @@ import org.eclipse.jetty.http3.internal.parser.MessageParser;
 import org.eclipse.jetty.http3.internal.parser.ParserListener;
 import org.eclipse.jetty.io.AbstractConnection;
 import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.RetainableByteBuffer;
 import org.eclipse.jetty.io.RetainableByteBufferPool;
 import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
@@ -35,7 +36,7 @@ import org.eclipse.jetty.util.thread.AutoLock;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
-public abstract class HTTP3StreamConnection extends AbstractConnection
+public abstract class HTTP3StreamConnection extends AbstractConnection implements Connection.UpgradeFrom
 {
     private static final Logger LOG = LoggerFactory.getLogger(HTTP3StreamConnection.class);
     // An empty DATA frame is the sequence of bytes [0x0, 0x0].
@@ -92,6 +93,21 @@ public abstract class HTTP3StreamConnection extends AbstractConnection
         super.onClose(cause);
     }
 
+    @Override
+    public ByteBuffer onUpgradeFrom()
+    {
+        if (!hasBuffer())
+            return null;
+        ByteBuffer byteBuffer = buffer.getBuffer();
+        int remaining = byteBuffer.remaining();
+        ByteBuffer copy = byteBuffer.isDirect() ? ByteBuffer.allocateDirect(remaining) : ByteBuffer.allocate(remaining);
+        copy.put(byteBuffer);
+        buffer.release();
+        buffer = null;
+        copy.flip();
+        return copy;
+    }
+
     @Override
     protected boolean onReadTimeout(Throwable timeout)
     {
@@ -148,6 +164,16 @@ public abstract class HTTP3StreamConnection extends AbstractConnection
                 throw new IllegalStateException();
             action.run();
 
+            // The stream may have been upgraded, for example to a WebTransport stream.
+            if (getEndPoint().getConnection() != this)
+                return;
+            // The stream may have been rejected, for example an unknown WebTransport stream.
+            if (!getEndPoint().isOpen())
+            {
+                tryReleaseBuffer(true);
+                return;
+            }
+
             // TODO: we should also exit if the connection was closed due to errors.
             //  This can be done by overriding relevant methods in MessageListener.
 
@@ -557,5 +583,14 @@ public abstract class HTTP3StreamConnection extends AbstractConnection
             if (!event.compareAndSet(null, () -> processData(frame, delegate)))
                 throw new IllegalStateException();
         }
+
+        @Override
+        public void onWebTransportStream(long streamId, long sessionId)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("received WebTransport stream #{} for session #{}", streamId, sessionId);
+            if (!event.compareAndSet(null, () -> super.onWebTransportStream(streamId, sessionId)))
+                throw new IllegalStateException();
+        }
     }
 }
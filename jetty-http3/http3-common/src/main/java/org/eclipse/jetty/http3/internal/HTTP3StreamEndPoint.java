@@ -0,0 +1,266 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.internal;
+
+import java.io.IOException;
+import java.net.SocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CompletionException;
+
+import org.eclipse.jetty.http3.api.Stream;
+import org.eclipse.jetty.http3.frames.DataFrame;
+import org.eclipse.jetty.io.AbstractEndPoint;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.util.BufferUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link org.eclipse.jetty.io.EndPoint} over an HTTP/3 stream, used
+ * to tunnel other protocols, for example WebSocket via the extended CONNECT
+ * method specified by RFC 9220.</p>
+ * <p>Bytes are read from and written to HTTP/3 DATA frames; shutting down the
+ * output sends an empty DATA frame with the {@code last} flag set.</p>
+ * <p>The stream that owns this EndPoint must forward its data available,
+ * idle timeout and failure events to {@link #onDataAvailable()},
+ * {@link #onIdleTimeout(Throwable)} and {@link #onFailure(Throwable)}.</p>
+ */
+public class HTTP3StreamEndPoint extends AbstractEndPoint
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HTTP3StreamEndPoint.class);
+
+    private final HTTP3Stream stream;
+    private Stream.Data data;
+    private volatile CompletableFuture<Stream> writing;
+
+    public HTTP3StreamEndPoint(HTTP3Stream stream)
+    {
+        super(stream.getEndPoint().getScheduler());
+        this.stream = stream;
+    }
+
+    public HTTP3Stream getStream()
+    {
+        return stream;
+    }
+
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return stream.getSession().getLocalSocketAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return stream.getSession().getRemoteSocketAddress();
+    }
+
+    @Override
+    public long getIdleTimeout()
+    {
+        return stream.getIdleTimeout();
+    }
+
+    @Override
+    public void setIdleTimeout(long idleTimeout)
+    {
+        // The idle timeout is enforced by the stream.
+        stream.setIdleTimeout(idleTimeout);
+    }
+
+    @Override
+    public Object getTransport()
+    {
+        return stream;
+    }
+
+    @Override
+    public int fill(ByteBuffer buffer) throws IOException
+    {
+        if (isInputShutdown())
+            return -1;
+
+        Stream.Data data = this.data;
+        if (data == null)
+        {
+            data = stream.readData();
+            if (LOG.isDebugEnabled())
+                LOG.debug("read {} on {}", data, this);
+            if (data == null)
+                return 0;
+            this.data = data;
+        }
+
+        ByteBuffer content = data.getByteBuffer();
+        int filled = BufferUtil.append(buffer, content);
+        if (!content.hasRemaining())
+        {
+            this.data = null;
+            data.complete();
+            if (data.isLast())
+            {
+                shutdownInput();
+                if (filled == 0)
+                    return -1;
+            }
+        }
+        return filled;
+    }
+
+    @Override
+    public boolean flush(ByteBuffer... buffers) throws IOException
+    {
+        if (isOutputShutdown())
+            throw new ClosedChannelException();
+
+        CompletableFuture<Stream> writing = this.writing;
+        if (writing != null)
+        {
+            if (!writing.isDone())
+                return false;
+            try
+            {
+                writing.join();
+            }
+            catch (CompletionException x)
+            {
+                throw new IOException(x.getCause());
+            }
+        }
+
+        for (ByteBuffer buffer : buffers)
+        {
+            if (!buffer.hasRemaining())
+                continue;
+            // Consume the buffer now, the WriteFlusher
+            // keeps it until the write is completed.
+            ByteBuffer content = buffer.slice();
+            buffer.position(buffer.limit());
+            if (LOG.isDebugEnabled())
+                LOG.debug("writing {} bytes on {}", content.remaining(), this);
+            writing = stream.data(new DataFrame(content, false));
+            this.writing = writing;
+            writing.whenComplete((s, x) -> getWriteFlusher().completeWrite());
+            return false;
+        }
+        return true;
+    }
+
+    @Override
+    protected void onIncompleteFlush()
+    {
+        CompletableFuture<Stream> writing = this.writing;
+        // The write may have completed before the WriteFlusher was ready.
+        if (writing != null && writing.isDone())
+            getWriteFlusher().completeWrite();
+    }
+
+    @Override
+    protected void needsFillInterest()
+    {
+        stream.demand();
+    }
+
+    @Override
+    protected void doShutdownOutput()
+    {
+        DataFrame last = new DataFrame(BufferUtil.EMPTY_BUFFER, true);
+        CompletableFuture<Stream> writing = this.writing;
+        if (writing == null)
+            this.writing = stream.data(last);
+        else
+            this.writing = writing.handle((s, x) -> stream).thenCompose(s -> s.data(last));
+    }
+
+    @Override
+    protected void doClose()
+    {
+        Stream.Data data = this.data;
+        this.data = null;
+        if (data != null)
+            data.complete();
+        CompletableFuture<Stream> writing = this.writing;
+        if (writing == null || writing.isDone())
+            reset();
+        else
+            writing.whenComplete((s, x) -> reset());
+    }
+
+    private void reset()
+    {
+        // If both sides have sent the last frame, the stream is already closed.
+        if (!stream.isClosed())
+            stream.reset(HTTP3ErrorCode.NO_ERROR.code(), new ClosedChannelException());
+    }
+
+    @Override
+    public void upgrade(Connection newConnection)
+    {
+        // The tunnelled connection is the first connection of this EndPoint.
+        if (getConnection() == null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("upgrading to {} on {}", newConnection, this);
+            setConnection(newConnection);
+            newConnection.onOpen();
+        }
+        else
+        {
+            super.upgrade(newConnection);
+        }
+    }
+
+    /**
+     * <p>Notifies that DATA frames are available to be read.</p>
+     *
+     * @return a task that fills this EndPoint
+     */
+    public Runnable onDataAvailable()
+    {
+        return getFillInterest()::fillable;
+    }
+
+    /**
+     * @param failure the idle timeout failure
+     * @return whether the stream should be closed
+     */
+    public boolean onIdleTimeout(Throwable failure)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("idle timeout on {}", this, failure);
+        Connection connection = getConnection();
+        if (connection != null)
+            return connection.onIdleExpired();
+        return true;
+    }
+
+    /**
+     * @param failure the stream failure
+     */
+    public void onFailure(Throwable failure)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("failure on {}", this, failure);
+        close(failure);
+    }
+
+    @Override
+    public String toEndPointString()
+    {
+        return String.format("%s#%d", super.toEndPointString(), stream.getId());
+    }
+}
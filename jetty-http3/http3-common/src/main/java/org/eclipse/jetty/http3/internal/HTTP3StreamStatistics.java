@@ -0,0 +1,130 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.internal;
+
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+
+import org.eclipse.jetty.http3.api.Stream;
+import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
+
+/**
+ * <p>The mutable implementation of {@link Stream.Statistics} for HTTP/3 streams.</p>
+ * <p>Flow control stalls are tracked by the {@link QuicStreamEndPoint}
+ * of the stream, that knows when writes cannot be completed.</p>
+ */
+public class HTTP3StreamStatistics implements Stream.Statistics
+{
+    private final AtomicLong bytesSent = new AtomicLong();
+    private final AtomicLong bytesReceived = new AtomicLong();
+    private final AtomicLong framesSent = new AtomicLong();
+    private final AtomicLong framesReceived = new AtomicLong();
+    private final AtomicLong headOfLineBlockingNanos = new AtomicLong();
+    private final QuicStreamEndPoint endPoint;
+
+    public HTTP3StreamStatistics(QuicStreamEndPoint endPoint)
+    {
+        this.endPoint = endPoint;
+    }
+
+    @Override
+    public long getBytesSent()
+    {
+        return bytesSent.get();
+    }
+
+    @Override
+    public long getBytesReceived()
+    {
+        return bytesReceived.get();
+    }
+
+    @Override
+    public long getFramesSent()
+    {
+        return framesSent.get();
+    }
+
+    @Override
+    public long getFramesReceived()
+    {
+        return framesReceived.get();
+    }
+
+    @Override
+    public long getFlowControlStalls()
+    {
+        return endPoint.getFlushStalls();
+    }
+
+    @Override
+    public long getFlowControlStallTime()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(endPoint.getFlushStallNanos());
+    }
+
+    @Override
+    public long getHeadOfLineBlockingTime()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(headOfLineBlockingNanos.get());
+    }
+
+    /**
+     * @param dataBytes the number of DATA bytes of the frame sent, or 0 for non-DATA frames
+     */
+    public void onFrameSent(long dataBytes)
+    {
+        framesSent.incrementAndGet();
+        if (dataBytes > 0)
+            bytesSent.addAndGet(dataBytes);
+    }
+
+    public void onFrameReceived()
+    {
+        framesReceived.incrementAndGet();
+    }
+
+    /**
+     * @param dataBytes the number of DATA bytes read by the application
+     */
+    public void onDataRead(long dataBytes)
+    {
+        if (dataBytes > 0)
+            bytesReceived.addAndGet(dataBytes);
+    }
+
+    /**
+     * @param nanos the time, in nanoseconds, a frame waited to be written
+     */
+    public void onHeadOfLineBlocked(long nanos)
+    {
+        if (nanos > 0)
+            headOfLineBlockingNanos.addAndGet(nanos);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{sent=%d/%d,received=%d/%d,stalls=%d/%dms,hol=%dms}",
+            getClass().getSimpleName(),
+            hashCode(),
+            getFramesSent(),
+            getBytesSent(),
+            getFramesReceived(),
+            getBytesReceived(),
+            getFlowControlStalls(),
+            getFlowControlStallTime(),
+            getHeadOfLineBlockingTime());
+    }
+}
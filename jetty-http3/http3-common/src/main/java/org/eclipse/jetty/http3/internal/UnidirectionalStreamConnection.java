@@ -16,6 +16,7 @@ package org.eclipse.jetty.http3.internal;
 import java.nio.ByteBuffer;
 import java.util.concurrent.Executor;
 
+import org.eclipse.jetty.http3.frames.FrameType;
 import org.eclipse.jetty.http3.internal.parser.ControlParser;
 import org.eclipse.jetty.http3.internal.parser.ParserListener;
 import org.eclipse.jetty.http3.qpack.QpackDecoder;
@@ -31,6 +32,7 @@ import org.slf4j.LoggerFactory;
 
 public class UnidirectionalStreamConnection extends AbstractConnection implements Connection.UpgradeFrom
 {
+    public static final long WEBTRANSPORT_STREAM_TYPE = 0x54;
     private static final Logger LOG = LoggerFactory.getLogger(UnidirectionalStreamConnection.class);
 
     private final ByteBufferPool byteBufferPool;
@@ -39,6 +41,7 @@ public class UnidirectionalStreamConnection extends AbstractConnection implement
     private final ParserListener listener;
     private final VarLenInt parser = new VarLenInt();
     private boolean useInputDirectByteBuffers = true;
+    private boolean webTransport;
     private ByteBuffer buffer;
 
     public UnidirectionalStreamConnection(QuicStreamEndPoint endPoint, Executor executor, ByteBufferPool byteBufferPool, QpackEncoder encoder, QpackDecoder decoder, ParserListener listener)
@@ -101,12 +104,13 @@ public class UnidirectionalStreamConnection extends AbstractConnection implement
 
                 if (filled > 0)
                 {
-                    if (parser.decode(buffer, this::detectAndUpgrade))
+                    if (decode())
                         break;
                 }
                 else if (filled == 0)
                 {
                     byteBufferPool.release(buffer);
+                    buffer = null;
                     fillInterested();
                     break;
                 }
@@ -129,9 +133,49 @@ public class UnidirectionalStreamConnection extends AbstractConnection implement
         }
     }
 
+    private boolean decode()
+    {
+        if (!webTransport)
+        {
+            if (!parser.decode(buffer, this::detectAndUpgrade))
+                return false;
+            if (!webTransport)
+                return true;
+        }
+        // WebTransport streams have the session id after the stream type.
+        return parser.decode(buffer, this::upgradeToWebTransport);
+    }
+
+    private void upgradeToWebTransport(long sessionId)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("upgrading to WebTransport session #{} on {}", sessionId, this);
+        listener.onWebTransportStream(getEndPoint().getStreamId(), sessionId);
+    }
+
     private void detectAndUpgrade(long streamType)
     {
-        if (streamType == ControlStreamConnection.STREAM_TYPE)
+        // Server initiated bidirectional streams are only allowed for WebTransport,
+        // and they start with the WEBTRANSPORT_STREAM frame type rather than a stream type.
+        boolean bidirectional = StreamType.from(getEndPoint().getStreamId()) == StreamType.SERVER_BIDIRECTIONAL;
+        if (bidirectional)
+        {
+            if (streamType == FrameType.WEBTRANSPORT_STREAM.type())
+            {
+                webTransport = true;
+            }
+            else
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("unsupported bidirectional stream {}, closing {}", Long.toHexString(streamType), this);
+                getEndPoint().close(HTTP3ErrorCode.STREAM_CREATION_ERROR.code(), null);
+            }
+        }
+        else if (streamType == WEBTRANSPORT_STREAM_TYPE)
+        {
+            webTransport = true;
+        }
+        else if (streamType == ControlStreamConnection.STREAM_TYPE)
         {
             ControlParser parser = new ControlParser(listener);
             ControlStreamConnection newConnection = new ControlStreamConnection(getEndPoint(), getExecutor(), byteBufferPool, parser);
@@ -41,7 +41,9 @@ public class MessageParser
     private final long streamId;
     private final BooleanSupplier isLast;
     private BodyParser unknownBodyParser;
+    private ParserListener wrappedListener;
     private State state = State.HEADER;
+    private boolean firstFrame = true;
     protected boolean dataMode;
 
     public MessageParser(ParserListener listener, QpackDecoder decoder, long streamId, BooleanSupplier isLast)
@@ -59,6 +61,7 @@ public class MessageParser
         this.bodyParsers[FrameType.HEADERS.type()] = new HeadersBodyParser(headerParser, listener, decoder, streamId, isLast);
         this.bodyParsers[FrameType.PUSH_PROMISE.type()] = new PushPromiseBodyParser(headerParser, listener);
         this.unknownBodyParser = new UnknownBodyParser(headerParser, listener);
+        this.wrappedListener = listener;
     }
 
     private void reset()
@@ -102,6 +105,9 @@ public class MessageParser
                     {
                         if (headerParser.parse(buffer))
                         {
+                            if (headerParser.getFrameType() == FrameType.WEBTRANSPORT_STREAM.type())
+                                return parseWebTransportStream(buffer);
+                            firstFrame = false;
                             state = State.BODY;
                             // If we are in data mode, but we did not parse a DATA frame, bail out.
                             if (dataMode && headerParser.getFrameType() != FrameType.DATA.type())
@@ -192,6 +198,26 @@ public class MessageParser
         }
     }
 
+    private Result parseWebTransportStream(ByteBuffer buffer)
+    {
+        // SPEC: WEBTRANSPORT_STREAM is not a frame, it has no length but the
+        // WebTransport session id, and it must be the first bytes of the stream.
+        if (!firstFrame)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("invalid WEBTRANSPORT_STREAM after other frames on stream #{}", streamId);
+            sessionFailure(buffer, HTTP3ErrorCode.FRAME_UNEXPECTED_ERROR.code(), "invalid_frame_sequence", new IOException("invalid WebTransport stream"));
+            return Result.NO_FRAME;
+        }
+        long sessionId = headerParser.getFrameLength();
+        firstFrame = false;
+        reset();
+        if (LOG.isDebugEnabled())
+            LOG.debug("parsed WebTransport stream #{} for session #{}", streamId, sessionId);
+        wrappedListener.onWebTransportStream(streamId, sessionId);
+        return Result.FRAME;
+    }
+
     private void sessionFailure(ByteBuffer buffer, long error, String reason, Throwable failure)
     {
         unknownBodyParser.sessionFailure(buffer, error, reason, failure);
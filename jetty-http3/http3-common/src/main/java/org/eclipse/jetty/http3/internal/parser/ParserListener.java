@@ -44,6 +44,16 @@ public interface ParserListener
     {
     }
 
+    /**
+     * <p>Callback method invoked when a stream is detected to be a WebTransport stream.</p>
+     *
+     * @param streamId the stream id
+     * @param sessionId the WebTransport session id the stream belongs to
+     */
+    public default void onWebTransportStream(long streamId, long sessionId)
+    {
+    }
+
     public static class Wrapper implements ParserListener
     {
         protected final ParserListener listener;
@@ -82,5 +92,11 @@ public interface ParserListener
         {
             listener.onSessionFailure(error, reason, failure);
         }
+
+        @Override
+        public void onWebTransportStream(long streamId, long sessionId)
+        {
+            listener.onWebTransportStream(streamId, sessionId);
+        }
     }
 }
@@ -0,0 +1,83 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.client.http;
+
+import java.nio.ByteBuffer;
+import java.util.concurrent.CompletableFuture;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http3.api.Stream;
+
+/**
+ * <p>Utility methods to exchange HTTP datagrams (RFC 9297) associated
+ * with an HTTP/3 request sent by {@link org.eclipse.jetty.client.HttpClient}.</p>
+ * <p>HTTP datagrams must be enabled on both the QUIC and the HTTP/3
+ * configurations of {@link HttpClientTransportOverHTTP3}, and must
+ * be enabled by the server as well.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * Request request = httpClient.newRequest("https://localhost:8443/path");
+ * HttpClientDatagrams.listener(request, payload -> process(payload));
+ * request.onResponseHeaders(response -> HttpClientDatagrams.send(request, ByteBuffer.wrap(bytes)))
+ *     .send(result -> {});
+ * }</pre>
+ *
+ * @see org.eclipse.jetty.http3.HTTP3Configuration#setDatagramsEnabled(boolean)
+ */
+public class HttpClientDatagrams
+{
+    /**
+     * <p>The name of the request attribute that holds the listener of HTTP datagrams.</p>
+     */
+    public static final String LISTENER_ATTRIBUTE = HttpClientDatagrams.class.getName() + ".listener";
+
+    /**
+     * <p>Sets the listener of HTTP datagrams associated with the given request.</p>
+     * <p>The listener must be set before the request is sent; it is invoked by
+     * the thread that processes the QUIC connection, so it must not block.
+     * HTTP datagrams received before the response headers may be discarded.</p>
+     *
+     * @param request the request
+     * @param listener the listener of HTTP datagrams
+     * @return the request
+     */
+    public static Request listener(Request request, Consumer<ByteBuffer> listener)
+    {
+        return request.attribute(LISTENER_ATTRIBUTE, listener);
+    }
+
+    /**
+     * <p>Sends the given payload as an HTTP datagram associated with the given request.</p>
+     * <p>HTTP datagrams can only be sent after the request headers have been sent.</p>
+     *
+     * @param request the request
+     * @param payload the HTTP datagram payload
+     * @return a future completed when the HTTP datagram has been queued to be sent
+     */
+    public static CompletableFuture<Void> send(Request request, ByteBuffer payload)
+    {
+        Stream stream = null;
+        if (request instanceof HttpRequest)
+            stream = (Stream)((HttpRequest)request).getConversation().getAttribute(Stream.class.getName());
+        if (stream == null)
+            return CompletableFuture.failedFuture(new IllegalStateException("no HTTP/3 stream for request " + request));
+        return stream.datagram(payload).thenApply(s -> null);
+    }
+
+    private HttpClientDatagrams()
+    {
+    }
+}
@@ -19,6 +19,7 @@ import java.time.Duration;
 import java.util.List;
 import java.util.Map;
 import java.util.Objects;
+import java.util.function.Predicate;
 
 import org.eclipse.jetty.client.AbstractHttpClientTransport;
 import org.eclipse.jetty.client.HttpClient;
@@ -28,6 +29,8 @@ import org.eclipse.jetty.client.HttpRequest;
 import org.eclipse.jetty.client.MultiplexConnectionPool;
 import org.eclipse.jetty.client.MultiplexHttpDestination;
 import org.eclipse.jetty.client.Origin;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http3.HTTP3Configuration;
 import org.eclipse.jetty.http3.client.HTTP3Client;
 import org.eclipse.jetty.http3.client.HTTP3ClientConnectionFactory;
@@ -42,8 +45,19 @@ import org.eclipse.jetty.quic.common.QuicSession;
 
 public class HttpClientTransportOverHTTP3 extends AbstractHttpClientTransport implements ProtocolSession.Factory
 {
+    /**
+     * <p>The default early data policy, that only allows requests with
+     * idempotent methods to be sent as 0-RTT early data.</p>
+     */
+    public static final Predicate<Request> IDEMPOTENT_EARLY_DATA = request ->
+    {
+        HttpMethod method = HttpMethod.fromString(request.getMethod());
+        return method != null && method.isIdempotent();
+    };
+
     private final HTTP3ClientConnectionFactory factory = new HTTP3ClientConnectionFactory();
     private final HTTP3Client client;
+    private Predicate<Request> earlyDataPolicy = IDEMPOTENT_EARLY_DATA;
 
     public HttpClientTransportOverHTTP3(HTTP3Client client)
     {
@@ -61,6 +75,26 @@ public class HttpClientTransportOverHTTP3 extends AbstractHttpClientTransport im
         return client;
     }
 
+    public Predicate<Request> getEarlyDataPolicy()
+    {
+        return earlyDataPolicy;
+    }
+
+    /**
+     * <p>Sets the policy that decides whether a request may be sent as 0-RTT early data.</p>
+     * <p>Early data is only sent when it is {@link org.eclipse.jetty.quic.common.QuicConfiguration#setEarlyDataEnabled(boolean)
+     * enabled} and a TLS session is resumed from a {@link org.eclipse.jetty.quic.client.SessionTicketStore};
+     * since early data may be replayed by an attacker, requests that are not allowed by this policy
+     * are deferred until the QUIC handshake is complete.</p>
+     *
+     * @param earlyDataPolicy the policy that returns true if the request may be sent as early data
+     * @see #IDEMPOTENT_EARLY_DATA
+     */
+    public void setEarlyDataPolicy(Predicate<Request> earlyDataPolicy)
+    {
+        this.earlyDataPolicy = Objects.requireNonNull(earlyDataPolicy);
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -14,20 +14,29 @@
 package org.eclipse.jetty.http3.client.http.internal;
 
 import java.nio.channels.AsynchronousCloseException;
+import java.util.ArrayDeque;
 import java.util.Iterator;
+import java.util.Queue;
 import java.util.Set;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.function.Predicate;
 
 import org.eclipse.jetty.client.ConnectionPool;
 import org.eclipse.jetty.client.HttpChannel;
+import org.eclipse.jetty.client.HttpClientTransport;
 import org.eclipse.jetty.client.HttpConnection;
 import org.eclipse.jetty.client.HttpDestination;
 import org.eclipse.jetty.client.HttpExchange;
 import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.HttpUpgrader;
 import org.eclipse.jetty.client.SendFailure;
+import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http3.client.http.HttpClientTransportOverHTTP3;
 import org.eclipse.jetty.http3.client.internal.HTTP3SessionClient;
+import org.eclipse.jetty.quic.common.QuicSession;
+import org.eclipse.jetty.util.thread.AutoLock;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -37,12 +46,34 @@ public class HttpConnectionOverHTTP3 extends HttpConnection implements Connectio
 
     private final Set<HttpChannel> activeChannels = ConcurrentHashMap.newKeySet();
     private final AtomicBoolean closed = new AtomicBoolean();
+    private final AutoLock lock = new AutoLock();
+    private final Queue<HttpExchange> deferredExchanges = new ArrayDeque<>();
     private final HTTP3SessionClient session;
+    private boolean handshakeCompleted;
 
     public HttpConnectionOverHTTP3(HttpDestination destination, HTTP3SessionClient session)
     {
         super(destination);
         this.session = session;
+        QuicSession quicSession = session.getProtocolSession().getQuicSession();
+        if (quicSession.isEarlyData())
+        {
+            quicSession.addEventListener(new QuicSession.Listener()
+            {
+                @Override
+                public void onHandshakeCompleted(QuicSession session)
+                {
+                    HttpConnectionOverHTTP3.this.onHandshakeCompleted();
+                }
+            });
+            // The handshake may have completed before the listener was added.
+            if (!quicSession.isConnectionInEarlyData() && quicSession.isConnectionEstablished())
+                onHandshakeCompleted();
+        }
+        else
+        {
+            handshakeCompleted = true;
+        }
     }
 
     public HTTP3SessionClient getSession()
@@ -64,6 +95,12 @@ public class HttpConnectionOverHTTP3 extends HttpConnection implements Connectio
         return session.getMaxLocalStreams();
     }
 
+    @Override
+    public String getProtocol()
+    {
+        return "h3";
+    }
+
     @Override
     protected Iterator<HttpChannel> getHttpChannels()
     {
@@ -77,6 +114,26 @@ public class HttpConnectionOverHTTP3 extends HttpConnection implements Connectio
         request.version(HttpVersion.HTTP_3);
         normalizeRequest(request);
 
+        if (deferEarlyData(exchange))
+            return null;
+
+        return sendExchange(exchange);
+    }
+
+    @Override
+    protected void normalizeRequest(HttpRequest request)
+    {
+        super.normalizeRequest(request);
+        if (request instanceof HttpUpgrader.Factory)
+        {
+            HttpUpgrader upgrader = ((HttpUpgrader.Factory)request).newHttpUpgrader(HttpVersion.HTTP_3);
+            request.getConversation().setAttribute(HttpUpgrader.class.getName(), upgrader);
+            upgrader.prepare(request);
+        }
+    }
+
+    private SendFailure sendExchange(HttpExchange exchange)
+    {
         // One connection maps to N channels, so one channel for each exchange.
         HttpChannelOverHTTP3 channel = newHttpChannel();
         activeChannels.add(channel);
@@ -84,6 +141,46 @@ public class HttpConnectionOverHTTP3 extends HttpConnection implements Connectio
         return send(channel, exchange);
     }
 
+    private boolean deferEarlyData(HttpExchange exchange)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (handshakeCompleted)
+                return false;
+            HttpClientTransport transport = getHttpClient().getTransport();
+            Predicate<Request> earlyDataPolicy = transport instanceof HttpClientTransportOverHTTP3
+                ? ((HttpClientTransportOverHTTP3)transport).getEarlyDataPolicy()
+                : HttpClientTransportOverHTTP3.IDEMPOTENT_EARLY_DATA;
+            if (earlyDataPolicy.test(exchange.getRequest()))
+                return false;
+            if (LOG.isDebugEnabled())
+                LOG.debug("deferring until handshake completion {}", exchange);
+            deferredExchanges.offer(exchange);
+            return true;
+        }
+    }
+
+    private void onHandshakeCompleted()
+    {
+        Queue<HttpExchange> exchanges;
+        try (AutoLock l = lock.lock())
+        {
+            if (handshakeCompleted)
+                return;
+            handshakeCompleted = true;
+            exchanges = new ArrayDeque<>(deferredExchanges);
+            deferredExchanges.clear();
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("handshake completed, sending {} deferred exchange(s) on {}", exchanges.size(), this);
+        for (HttpExchange exchange : exchanges)
+        {
+            SendFailure failure = sendExchange(exchange);
+            if (failure != null)
+                exchange.getRequest().abort(failure.failure);
+        }
+    }
+
     protected HttpChannelOverHTTP3 newHttpChannel()
     {
         return new HttpChannelOverHTTP3(getHttpDestination(), this, getSession());
@@ -124,6 +221,13 @@ public class HttpConnectionOverHTTP3 extends HttpConnection implements Connectio
 
     private void abort(Throwable failure)
     {
+        Queue<HttpExchange> exchanges;
+        try (AutoLock l = lock.lock())
+        {
+            exchanges = new ArrayDeque<>(deferredExchanges);
+            deferredExchanges.clear();
+        }
+        exchanges.forEach(exchange -> exchange.getRequest().abort(failure));
         for (HttpChannel channel : activeChannels)
         {
             HttpExchange exchange = channel.getHttpExchange();
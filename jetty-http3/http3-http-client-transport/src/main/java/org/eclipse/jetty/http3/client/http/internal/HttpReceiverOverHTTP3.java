@@ -15,17 +15,25 @@ package org.eclipse.jetty.http3.client.http.internal;
 
 import java.io.UncheckedIOException;
 import java.nio.ByteBuffer;
+import java.util.function.Consumer;
 
+import org.eclipse.jetty.client.HttpConversation;
 import org.eclipse.jetty.client.HttpExchange;
 import org.eclipse.jetty.client.HttpReceiver;
+import org.eclipse.jetty.client.HttpRequest;
 import org.eclipse.jetty.client.HttpResponse;
+import org.eclipse.jetty.client.HttpUpgrader;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http3.api.Stream;
+import org.eclipse.jetty.http3.client.http.HttpClientDatagrams;
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.internal.HTTP3ErrorCode;
+import org.eclipse.jetty.http3.internal.HTTP3Stream;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
+import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.thread.Invocable;
 import org.slf4j.Logger;
@@ -35,6 +43,8 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
 {
     private static final Logger LOG = LoggerFactory.getLogger(HttpReceiverOverHTTP3.class);
     private volatile boolean notifySuccess;
+    private volatile HTTP3StreamEndPoint tunnel;
+    private volatile Consumer<ByteBuffer> datagramListener;
 
     protected HttpReceiverOverHTTP3(HttpChannelOverHTTP3 channel)
     {
@@ -74,9 +84,16 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
     }
 
     @Override
+    @SuppressWarnings("unchecked")
     public void onNewStream(Stream.Client stream)
     {
         getHttpChannel().setStream(stream);
+        // The listener is captured here because HTTP datagrams
+        // may be received after the exchange is terminated,
+        // for example when the request has been tunnelled.
+        HttpExchange exchange = getHttpExchange();
+        Object listener = exchange == null ? null : exchange.getRequest().getAttributes().get(HttpClientDatagrams.LISTENER_ATTRIBUTE);
+        datagramListener = (Consumer<ByteBuffer>)listener;
     }
 
     @Override
@@ -99,12 +116,31 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
                     return;
             }
 
-            // TODO: add support for HttpMethod.CONNECT.
+            HttpRequest httpRequest = exchange.getRequest();
+            boolean tunnelled = MetaData.isTunnel(httpRequest.getMethod(), httpResponse.getStatus());
+            if (tunnelled)
+            {
+                HTTP3StreamEndPoint endPoint = new HTTP3StreamEndPoint((HTTP3Stream)stream);
+                long idleTimeout = httpRequest.getIdleTimeout();
+                if (idleTimeout > 0)
+                    endPoint.setIdleTimeout(idleTimeout);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Successful HTTP3 tunnel on {} via {}", stream, endPoint);
+                tunnel = endPoint;
+                HttpConversation conversation = httpRequest.getConversation();
+                conversation.setAttribute(EndPoint.class.getName(), endPoint);
+                HttpUpgrader upgrader = (HttpUpgrader)conversation.getAttribute(HttpUpgrader.class.getName());
+                if (upgrader != null)
+                    upgrade(upgrader, httpResponse, endPoint);
+            }
 
             notifySuccess = frame.isLast();
             if (responseHeaders(exchange))
             {
                 int status = response.getStatus();
+                // The tunnelled protocol demands DATA frames.
+                if (tunnelled)
+                    return;
                 if (frame.isLast() || HttpStatus.isInterim(status))
                     responseSuccess(exchange);
                 else
@@ -118,9 +154,28 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
         }
     }
 
+    private void upgrade(HttpUpgrader upgrader, HttpResponse response, EndPoint endPoint)
+    {
+        try
+        {
+            upgrader.upgrade(response, endPoint, Callback.from(Callback.NOOP::succeeded, this::responseFailure));
+        }
+        catch (Throwable x)
+        {
+            responseFailure(x);
+        }
+    }
+
     @Override
     public void onDataAvailable(Stream.Client stream)
     {
+        HTTP3StreamEndPoint endPoint = tunnel;
+        if (endPoint != null)
+        {
+            endPoint.onDataAvailable().run();
+            return;
+        }
+
         HttpExchange exchange = getHttpExchange();
         if (exchange == null)
             return;
@@ -192,9 +247,23 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
         responseSuccess(exchange);
     }
 
+    @Override
+    public void onDatagram(Stream.Client stream, ByteBuffer payload)
+    {
+        Consumer<ByteBuffer> listener = datagramListener;
+        if (LOG.isDebugEnabled())
+            LOG.debug("received datagram of {} bytes, notifying {} on {}", payload.remaining(), listener, this);
+        if (listener != null)
+            listener.accept(payload);
+    }
+
     @Override
     public boolean onIdleTimeout(Stream.Client stream, Throwable failure)
     {
+        HTTP3StreamEndPoint endPoint = tunnel;
+        if (endPoint != null)
+            return endPoint.onIdleTimeout(failure);
+
         HttpExchange exchange = getHttpExchange();
         if (exchange == null)
             return false;
@@ -205,6 +274,9 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
     @Override
     public void onFailure(Stream.Client stream, long error, Throwable failure)
     {
+        HTTP3StreamEndPoint endPoint = tunnel;
+        if (endPoint != null)
+            endPoint.onFailure(failure);
         responseFailure(failure);
     }
 
@@ -213,5 +285,6 @@ public class HttpReceiverOverHTTP3 extends HttpReceiver implements Stream.Client
     {
         super.reset();
         notifySuccess = false;
+        tunnel = null;
     }
 }
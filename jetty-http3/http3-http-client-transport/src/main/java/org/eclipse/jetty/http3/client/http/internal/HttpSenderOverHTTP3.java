@@ -141,6 +141,8 @@ public class HttpSenderOverHTTP3 extends HttpSender
         long idleTimeout = request.getIdleTimeout();
         if (idleTimeout > 0)
             ((HTTP3Stream)stream).setIdleTimeout(idleTimeout);
+        // Allow applications to send HTTP datagrams associated with the request.
+        request.getConversation().setAttribute(Stream.class.getName(), stream);
         return stream;
     }
 
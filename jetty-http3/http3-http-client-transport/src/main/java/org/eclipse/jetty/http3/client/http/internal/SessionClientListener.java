@@ -40,14 +40,29 @@ public class SessionClientListener implements Session.Client.Listener
     public void onConnect(Session.Client session, Throwable failure)
     {
         if (failure != null)
+        {
             failConnectionPromise(failure);
+        }
+        else if (((HTTP3SessionClient)session).getProtocolSession().getQuicSession().isEarlyData())
+        {
+            // With 0-RTT early data, do not wait for the server
+            // SETTINGS, so that requests can be sent immediately.
+            succeedConnectionPromise((HTTP3SessionClient)session);
+        }
     }
 
     @Override
     public void onSettings(Session session, SettingsFrame frame)
     {
+        succeedConnectionPromise((HTTP3SessionClient)session);
+    }
+
+    private void succeedConnectionPromise(HTTP3SessionClient session)
+    {
+        if (connection.isMarked())
+            return;
         HttpDestination destination = (HttpDestination)context.get(HttpClientTransport.HTTP_DESTINATION_CONTEXT_KEY);
-        HttpConnectionOverHTTP3 connection = newHttpConnection(destination, (HTTP3SessionClient)session);
+        HttpConnectionOverHTTP3 connection = newHttpConnection(destination, session);
         if (this.connection.compareAndSet(null, connection, false, true))
             httpConnectionPromise().succeeded(connection);
     }
@@ -46,6 +46,7 @@ import static org.eclipse.jetty.http3.qpack.QpackException.QPACK_ENCODER_STREAM_
 public class QpackDecoder implements Dumpable
 {
     private static final Logger LOG = LoggerFactory.getLogger(QpackDecoder.class);
+    private static final int COMPRESSION_RATIO_MIN_DECODED_OCTETS = 64 * 1024;
 
     private final List<Instruction> _instructions = new ArrayList<>();
     private final List<MetaDataNotification> _metaDataNotifications = new ArrayList<>();
@@ -59,6 +60,9 @@ public class QpackDecoder implements Dumpable
     private int _maxHeadersSize;
     private int _maxBlockedStreams;
     private int _maxTableCapacity;
+    private int _maxCompressionRatio;
+    private long _encodedOctets;
+    private long _decodedOctets;
 
     private static class MetaDataNotification
     {
@@ -131,6 +135,53 @@ public class QpackDecoder implements Dumpable
         _maxTableCapacity = maxTableCapacity;
     }
 
+    public int getMaxCompressionRatio()
+    {
+        return _maxCompressionRatio;
+    }
+
+    /**
+     * <p>Sets the maximum ratio between the decoded header bytes and the
+     * QPACK encoded bytes received by this decoder over its lifetime.</p>
+     * <p>Very large ratios are typical of decompression bombs, where small
+     * field sections repeatedly reference large dynamic table entries.
+     * Since the HTTP/3 dynamic table capacity cannot be reduced after
+     * the SETTINGS frame has been sent, exceeding the ratio fails the
+     * decoding with a {@link QpackException.SessionException}.</p>
+     *
+     * @param maxCompressionRatio the max compression ratio, or {@code 0} to disable the check
+     */
+    public void setMaxCompressionRatio(int maxCompressionRatio)
+    {
+        _maxCompressionRatio = maxCompressionRatio;
+    }
+
+    /**
+     * @return the number of QPACK encoded octets of the field sections received
+     */
+    public long getEncodedOctets()
+    {
+        return _encodedOctets;
+    }
+
+    /**
+     * @return the number of header octets decoded, expressed as total
+     * of all name and value characters, plus 32 per field
+     */
+    public long getDecodedOctets()
+    {
+        return _decodedOctets;
+    }
+
+    /**
+     * @return the ratio between the decoded header octets and the encoded octets
+     */
+    public double getCompressionRatio()
+    {
+        long encoded = _encodedOctets;
+        return encoded == 0 ? 0 : (double)_decodedOctets / encoded;
+    }
+
     public interface Handler
     {
         void onMetaData(long streamId, MetaData metadata, boolean wasBlocked);
@@ -159,6 +210,7 @@ public class QpackDecoder implements Dumpable
         if (maxHeaderSize > 0 && buffer.remaining() > maxHeaderSize)
             throw new QpackException.SessionException(QPACK_DECOMPRESSION_FAILED, "header_too_large");
 
+        _encodedOctets += buffer.remaining();
         _integerDecoder.setPrefix(8);
         int encodedInsertCount = _integerDecoder.decodeInt(buffer);
         if (encodedInsertCount < 0)
@@ -186,6 +238,7 @@ public class QpackDecoder implements Dumpable
             if (requiredInsertCount <= insertCount)
             {
                 MetaData metaData = encodedFieldSection.decode(_context, maxHeaderSize);
+                checkCompressionRatio(encodedFieldSection);
                 if (LOG.isDebugEnabled())
                     LOG.debug("Decoded: streamId={}, metadata={}", streamId, metaData);
                 _metaDataNotifications.add(new MetaDataNotification(streamId, metaData, handler));
@@ -276,6 +329,7 @@ public class QpackDecoder implements Dumpable
                 iterator.remove();
                 long streamId = encodedFieldSection.getStreamId();
                 MetaData metaData = encodedFieldSection.decode(_context, getMaxHeadersSize());
+                checkCompressionRatio(encodedFieldSection);
                 if (_blockedStreams.get(streamId).decrementAndGet() <= 0)
                     _blockedStreams.remove(streamId);
                 if (LOG.isDebugEnabled())
@@ -288,6 +342,16 @@ public class QpackDecoder implements Dumpable
         }
     }
 
+    private void checkCompressionRatio(EncodedFieldSection encodedFieldSection) throws QpackException
+    {
+        _decodedOctets += encodedFieldSection.getDecodedSize();
+        int maxRatio = getMaxCompressionRatio();
+        // Avoid false positives for few small field sections
+        // that are mostly encoded with static table references.
+        if (maxRatio > 0 && _decodedOctets >= COMPRESSION_RATIO_MIN_DECODED_OCTETS && _decodedOctets > _encodedOctets * maxRatio)
+            throw new QpackException.SessionException(QPACK_DECOMPRESSION_FAILED, "compression_ratio_exceeded");
+    }
+
     private static int decodeInsertCount(int encInsertCount, int totalNumInserts, int maxTableCapacity) throws QpackException
     {
         if (encInsertCount == 0)
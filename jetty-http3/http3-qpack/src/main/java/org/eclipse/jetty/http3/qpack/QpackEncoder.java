@@ -99,6 +99,8 @@ public class QpackEncoder implements Dumpable
     private int _blockedStreams;
     private int _maxHeadersSize;
     private int _maxTableCapacity;
+    private long _encodedOctets;
+    private long _headerOctets;
 
     public QpackEncoder(Instruction.Handler handler)
     {
@@ -174,6 +176,40 @@ public class QpackEncoder implements Dumpable
         }
     }
 
+    /**
+     * @return the number of QPACK encoded octets of the field sections produced
+     */
+    public long getEncodedOctets()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return _encodedOctets;
+        }
+    }
+
+    /**
+     * @return the number of header octets encoded, expressed as total
+     * of all name and value characters, plus 32 per field
+     */
+    public long getHeaderOctets()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return _headerOctets;
+        }
+    }
+
+    /**
+     * @return the ratio between the header octets and the encoded octets
+     */
+    public double getCompressionRatio()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return _encodedOctets == 0 ? 0 : (double)_headerOctets / _encodedOctets;
+        }
+    }
+
     /**
      * <p>Encodes a {@link MetaData} object into the supplied {@link ByteBuffer} for a specific HTTP/s stream.</p>
      * <p>This method may generate instructions to be sent back over the Encoder stream to the remote Decoder.</p>
@@ -225,9 +261,12 @@ public class QpackEncoder implements Dumpable
 
             try
             {
+                int pos = buffer.position();
+                int headerSize = 0;
                 int requiredInsertCount = 0;
                 for (HttpField field : new Http3Fields(metadata))
                 {
+                    headerSize += field.getName().length() + (field.getValue() == null ? 0 : field.getValue().length()) + 32;
                     EncodableEntry entry = encode(streamInfo, field);
                     encodableEntries.add(entry);
 
@@ -262,6 +301,8 @@ public class QpackEncoder implements Dumpable
                     entry.encode(buffer, base);
                 }
 
+                _encodedOctets += buffer.position() - pos;
+                _headerOctets += headerSize;
                 notifyInstructionHandler();
             }
             catch (BufferOverflowException e)
@@ -44,6 +44,7 @@ public class EncodedFieldSection
     private final int _requiredInsertCount;
     private final int _base;
     private final QpackDecoder.Handler _handler;
+    private int _decodedSize;
 
     public EncodedFieldSection(long streamId, QpackDecoder.Handler handler, int requiredInsertCount, int base, ByteBuffer content) throws QpackException
     {
@@ -93,6 +94,15 @@ public class EncodedFieldSection
         return _requiredInsertCount;
     }
 
+    /**
+     * @return the size of the decoded fields, expressed as total
+     * of all name and value characters, plus 32 per field
+     */
+    public int getDecodedSize()
+    {
+        return _decodedSize;
+    }
+
     public MetaData decode(QpackContext context, int maxHeaderSize) throws QpackException
     {
         if (context.getDynamicTable().getInsertCount() < _requiredInsertCount)
@@ -104,6 +114,7 @@ public class EncodedFieldSection
             HttpField decodedField = encodedField.decode(context);
             metaDataBuilder.emit(decodedField);
         }
+        _decodedSize = metaDataBuilder.getSize();
         return metaDataBuilder.build();
     }
 
@@ -0,0 +1,91 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.qpack;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class CompressionRatioTest
+{
+    private QpackEncoder _encoder;
+    private QpackDecoder _decoder;
+    private final TestDecoderHandler _decoderHandler = new TestDecoderHandler();
+    private final TestEncoderHandler _encoderHandler = new TestEncoderHandler();
+
+    @BeforeEach
+    public void before()
+    {
+        _decoder = new QpackDecoder(_decoderHandler);
+        _decoder.setMaxHeadersSize(8 * 1024);
+        _decoder.setMaxTableCapacity(4 * 1024);
+        _decoder.setMaxBlockedStreams(5);
+
+        _encoder = new QpackEncoder(_encoderHandler);
+        _encoder.setMaxTableCapacity(4 * 1024);
+        _encoder.setTableCapacity(4 * 1024);
+        _encoder.setMaxBlockedStreams(5);
+    }
+
+    private void encodeDecode(long streamId, HttpFields fields) throws Exception
+    {
+        ByteBuffer buffer = ByteBuffer.allocate(8 * 1024);
+        _encoder.encode(buffer, streamId, new MetaData(HttpVersion.HTTP_3, fields));
+        _decoder.parseInstructions(_encoderHandler.getInstructionBuffer());
+        buffer.flip();
+        _decoder.decode(streamId, buffer, _decoderHandler);
+        _encoder.parseInstructions(_decoderHandler.getInstructionBuffer());
+        assertNotNull(_decoderHandler.getMetaData());
+    }
+
+    @Test
+    public void testCompressionRatio() throws Exception
+    {
+        HttpFields fields = HttpFields.build().add("x-big", "x".repeat(2000));
+        for (int i = 0; i < 8; i++)
+        {
+            encodeDecode(4 * i, fields);
+        }
+
+        // The large field is referenced from the dynamic table.
+        assertThat(_decoder.getCompressionRatio(), greaterThan(100.0));
+        assertThat(_encoder.getCompressionRatio(), greaterThan(100.0));
+    }
+
+    @Test
+    public void testMaxCompressionRatioExceeded() throws Exception
+    {
+        _decoder.setMaxCompressionRatio(100);
+
+        HttpFields fields = HttpFields.build().add("x-big", "x".repeat(2000));
+        QpackException.SessionException failure = assertThrows(QpackException.SessionException.class, () ->
+        {
+            for (int i = 0; i < 64; i++)
+            {
+                encodeDecode(4 * i, fields);
+            }
+        });
+        assertThat(failure.getMessage(), containsString("compression_ratio_exceeded"));
+    }
+}
@@ -40,6 +40,42 @@ etc/jetty-http3.xml
 ## Specifies the stream receive window (client to server) in bytes.
 # jetty.quic.bidirectionalStreamRecvWindow=2097152
 
+## The QUIC-LB server ID, as a hex string, to embed in connection IDs
+## so that load balancers can route packets to this server; empty for random connection IDs.
+# jetty.quic.lb.serverId=
+
+## The QUIC-LB config rotation codepoint, from 0 to 6.
+# jetty.quic.lb.configRotation=0
+
+## The QUIC-LB nonce length in bytes, from 4 to 18.
+# jetty.quic.lb.nonceLength=8
+
+## The QUIC-LB 16 bytes AES key, as a hex string; empty for plaintext connection IDs.
+# jetty.quic.lb.key=
+
+## When clients must validate their address with a Retry packet before
+## their connection is accepted: always, never or underLoad.
+# jetty.quic.retry.mode=always
+
+## The number of sessions above which the connector is under load, for the underLoad mode.
+# jetty.quic.retry.maxSessions=1000
+
+## A comma separated list of address patterns, such as 10.0.0.0/8,
+## whose clients must always validate their address.
+# jetty.quic.retry.addresses=
+
+## The lifetime, in milliseconds, of the address validation tokens sent in Retry packets.
+# jetty.quic.retry.tokenLifetime=10000
+
 ## Specifies the stream idle timeout, in milliseconds.
 # jetty.http3.streamIdleTimeout=30000
+
+## Specifies the maximum time, in milliseconds, a stream may be stalled
+## by flow control before being reset, to avoid slow-read attacks; 0 disables.
+# jetty.http3.maxStreamStallTime=0
+
+## Specifies the maximum ratio between decoded header bytes and QPACK bytes
+## received by a session, after which the session is closed,
+## to avoid decompression bomb attacks; 0 disables.
+# jetty.http3.maxDecoderCompressionRatio=0
 # end::documentation[]
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.server;
 
+import java.nio.ByteBuffer;
 import java.util.Objects;
 
 import org.eclipse.jetty.http.HttpField;
@@ -68,11 +69,11 @@ public class HTTP3ServerConnectionFactory extends AbstractHTTP3ServerConnectionF
         @Override
         public boolean onIdleTimeout(Session session)
         {
+            // Streams that tunnel other protocols are never idle.
             boolean result = session.getStreams().stream()
-                .map(stream -> (HTTP3Stream)stream)
-                .map(stream -> (HttpChannelOverHTTP3)stream.getAttachment())
+                .map(stream -> ((HTTP3Stream)stream).getAttachment())
                 .filter(Objects::nonNull)
-                .map(channel -> channel.getState().isIdle())
+                .map(attachment -> attachment instanceof HttpChannelOverHTTP3 && ((HttpChannelOverHTTP3)attachment).getState().isIdle())
                 .reduce(true, Boolean::logicalAnd);
             if (LOG.isDebugEnabled())
                 LOG.debug("{} idle timeout on {}", result ? "confirmed" : "ignored", session);
@@ -137,6 +138,12 @@ public class HTTP3ServerConnectionFactory extends AbstractHTTP3ServerConnectionF
             }
         }
 
+        @Override
+        public void onDatagram(Stream.Server stream, ByteBuffer payload)
+        {
+            getConnection().onDatagram((HTTP3Stream)stream, payload);
+        }
+
         @Override
         public boolean onIdleTimeout(Stream.Server stream, Throwable failure)
         {
@@ -0,0 +1,101 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.server;
+
+import java.nio.ByteBuffer;
+import java.util.concurrent.CompletableFuture;
+import java.util.function.Consumer;
+import javax.servlet.ServletRequest;
+
+import org.eclipse.jetty.http3.server.internal.HttpChannelOverHTTP3;
+import org.eclipse.jetty.server.HttpChannel;
+import org.eclipse.jetty.server.Request;
+
+/**
+ * <p>Utility methods to exchange HTTP datagrams (RFC 9297) associated
+ * with an HTTP/3 request, for example to implement UDP proxying.</p>
+ * <p>HTTP datagrams must be enabled on both the QUIC and the HTTP/3
+ * configurations, and must be enabled by the client as well.</p>
+ * <p>Typical usage:</p>
+ * <pre>{@code
+ * public void handle(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response)
+ * {
+ *     if (HttpDatagrams.isEnabled(request))
+ *     {
+ *         // Echo the datagrams back to the client.
+ *         HttpDatagrams.setListener(request, payload -> HttpDatagrams.send(request, payload));
+ *     }
+ * }
+ * }</pre>
+ *
+ * @see org.eclipse.jetty.http3.HTTP3Configuration#setDatagramsEnabled(boolean)
+ */
+public class HttpDatagrams
+{
+    /**
+     * @param request the request
+     * @return whether HTTP datagrams can be exchanged for the given request
+     */
+    public static boolean isEnabled(ServletRequest request)
+    {
+        HttpChannelOverHTTP3 channel = getHttpChannel(request);
+        return channel != null && channel.isDatagramsEnabled();
+    }
+
+    /**
+     * <p>Sets the listener of HTTP datagrams associated with the given request.</p>
+     * <p>The listener is invoked by the thread that processes the QUIC connection,
+     * so it must not block; the listener keeps being invoked after the request
+     * has been tunnelled, until the HTTP/3 stream is closed.</p>
+     *
+     * @param request the request
+     * @param listener the listener of HTTP datagrams, or null to discard HTTP datagrams
+     * @throws IllegalArgumentException if the request is not an HTTP/3 request
+     */
+    public static void setListener(ServletRequest request, Consumer<ByteBuffer> listener)
+    {
+        HttpChannelOverHTTP3 channel = getHttpChannel(request);
+        if (channel == null)
+            throw new IllegalArgumentException("not an HTTP/3 request: " + request);
+        channel.setDatagramListener(listener);
+    }
+
+    /**
+     * <p>Sends the given payload as an HTTP datagram associated with the given request.</p>
+     *
+     * @param request the request
+     * @param payload the HTTP datagram payload
+     * @return a future completed when the HTTP datagram has been queued to be sent
+     */
+    public static CompletableFuture<Void> send(ServletRequest request, ByteBuffer payload)
+    {
+        HttpChannelOverHTTP3 channel = getHttpChannel(request);
+        if (channel == null)
+            return CompletableFuture.failedFuture(new IllegalArgumentException("not an HTTP/3 request: " + request));
+        return channel.sendDatagram(payload);
+    }
+
+    private static HttpChannelOverHTTP3 getHttpChannel(ServletRequest request)
+    {
+        Request baseRequest = Request.getBaseRequest(request);
+        if (baseRequest == null)
+            return null;
+        HttpChannel channel = baseRequest.getHttpChannel();
+        return channel instanceof HttpChannelOverHTTP3 ? (HttpChannelOverHTTP3)channel : null;
+    }
+
+    private HttpDatagrams()
+    {
+    }
+}
@@ -13,13 +13,18 @@
 
 package org.eclipse.jetty.http3.server.internal;
 
+import java.io.IOException;
+
+import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http3.api.Session;
 import org.eclipse.jetty.http3.frames.Frame;
 import org.eclipse.jetty.http3.frames.GoAwayFrame;
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.frames.SettingsFrame;
+import org.eclipse.jetty.http3.internal.HTTP3ErrorCode;
 import org.eclipse.jetty.http3.internal.HTTP3Session;
 import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
+import org.eclipse.jetty.quic.common.StreamType;
 import org.eclipse.jetty.util.Callback;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
@@ -58,6 +63,12 @@ public class HTTP3SessionServer extends HTTP3Session implements Session.Server
         return new HTTP3StreamServer(this, endPoint, local);
     }
 
+    @Override
+    protected StreamType getLocalStreamType(boolean bidirectional)
+    {
+        return bidirectional ? StreamType.SERVER_BIDIRECTIONAL : StreamType.SERVER_UNIDIRECTIONAL;
+    }
+
     @Override
     public void onHeaders(long streamId, HeadersFrame frame, boolean wasBlocked)
     {
@@ -68,7 +79,16 @@ public class HTTP3SessionServer extends HTTP3Session implements Session.Server
             if (LOG.isDebugEnabled())
                 LOG.debug("received request {} on {}", frame, stream);
             if (stream != null)
+            {
+                MetaData.Request request = (MetaData.Request)frame.getMetaData();
+                if (!isConnectProtocolEnabled() && request.getProtocol() != null)
+                {
+                    // RFC 9220: an extended CONNECT that was not enabled is malformed.
+                    stream.reset(HTTP3ErrorCode.HTTP_MESSAGE_ERROR.code(), new IOException("extended_connect_disabled"));
+                    return;
+                }
                 stream.onRequest(frame);
+            }
         }
         else
         {
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.server.internal;
 
+import java.nio.ByteBuffer;
 import java.util.EnumSet;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.TimeoutException;
@@ -106,6 +107,21 @@ public class HTTP3StreamServer extends HTTP3Stream implements Stream.Server
         }
     }
 
+    @Override
+    protected void notifyDatagram(ByteBuffer payload)
+    {
+        Listener listener = this.listener;
+        try
+        {
+            if (listener != null)
+                listener.onDatagram(this, payload);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
     @Override
     protected boolean notifyIdleTimeout(TimeoutException timeout)
     {
@@ -14,6 +14,8 @@
 package org.eclipse.jetty.http3.server.internal;
 
 import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.concurrent.CompletableFuture;
 import java.util.function.Consumer;
 
 import org.eclipse.jetty.http.BadMessageException;
@@ -28,6 +30,7 @@ import org.eclipse.jetty.http.PreEncodedHttpField;
 import org.eclipse.jetty.http3.api.Stream;
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.internal.HTTP3Stream;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.server.Connector;
 import org.eclipse.jetty.server.HttpChannel;
@@ -68,6 +71,54 @@ public class HttpChannelOverHTTP3 extends HttpChannel
         stream.setIdleTimeout(timeoutMs);
     }
 
+    @Override
+    public long getStreamId()
+    {
+        return stream.getId();
+    }
+
+    @Override
+    public boolean isTunnellingSupported()
+    {
+        return true;
+    }
+
+    @Override
+    public EndPoint getTunnellingEndPoint()
+    {
+        return new HTTP3StreamEndPoint(stream);
+    }
+
+    /**
+     * @return whether HTTP datagrams have been negotiated on the HTTP/3 session of this channel
+     */
+    public boolean isDatagramsEnabled()
+    {
+        return stream.getSession().isDatagramsEnabled();
+    }
+
+    public CompletableFuture<Void> sendDatagram(ByteBuffer payload)
+    {
+        return stream.datagram(payload).thenApply(s -> null);
+    }
+
+    public void setDatagramListener(Consumer<ByteBuffer> listener)
+    {
+        ServerHTTP3StreamConnection connection = (ServerHTTP3StreamConnection)stream.getEndPoint().getConnection();
+        connection.setDatagramListener(listener);
+    }
+
+    @Override
+    protected boolean checkAndPrepareUpgrade()
+    {
+        return isTunnel() && getHttpTransport().prepareUpgrade();
+    }
+
+    private boolean isTunnel()
+    {
+        return MetaData.isTunnel(getRequest().getMethod(), getResponse().getStatus());
+    }
+
     void consumeInput()
     {
         getRequest().getHttpInput().consumeAll();
@@ -30,7 +30,10 @@ import org.eclipse.jetty.http3.api.Stream;
 import org.eclipse.jetty.http3.frames.DataFrame;
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.internal.HTTP3ErrorCode;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.server.HttpTransport;
+import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.thread.AutoLock;
@@ -254,6 +257,33 @@ public class HttpTransportOverHTTP3 implements HttpTransport
         throw new UnsupportedOperationException();
     }
 
+    /**
+     * @return true if error sent, false if upgraded or aborted.
+     */
+    boolean prepareUpgrade()
+    {
+        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)stream.getAttachment();
+        Request request = channel.getRequest();
+        if (request.getHttpInput().hasContent())
+            return channel.sendErrorOrAbort("Unexpected content in CONNECT request");
+
+        Connection connection = (Connection)request.getAttribute(UPGRADE_CONNECTION_ATTRIBUTE);
+        if (connection == null)
+            return channel.sendErrorOrAbort("No UPGRADE_CONNECTION_ATTRIBUTE available");
+
+        EndPoint endPoint = connection.getEndPoint();
+        endPoint.upgrade(connection);
+        stream.setAttachment(endPoint);
+
+        // Only now that we have switched the attachment, we can demand DATA frames to process them.
+        stream.demand();
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Upgrading to {}", connection);
+
+        return false;
+    }
+
     @Override
     public void onCompleted()
     {
@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.http3.server.internal;
 
+import java.nio.ByteBuffer;
 import java.util.HashMap;
 import java.util.Map;
 import java.util.concurrent.CompletableFuture;
@@ -58,6 +59,10 @@ public class ServerHTTP3Session extends ServerProtocolSession
         session = new HTTP3SessionServer(this, listener);
         addBean(session);
         session.setStreamIdleTimeout(configuration.getStreamIdleTimeout());
+        session.setMaxStreamStallTime(configuration.getMaxStreamStallTime());
+        session.setConnectProtocolEnabled(configuration.isConnectProtocolEnabled());
+        session.setLocalDatagramsEnabled(configuration.isDatagramsEnabled());
+        session.setWebTransportEnabled(configuration.isWebTransportEnabled());
 
         if (LOG.isDebugEnabled())
             LOG.debug("initializing HTTP/3 streams");
@@ -75,6 +80,7 @@ public class ServerHTTP3Session extends ServerProtocolSession
         QuicStreamEndPoint decoderEndPoint = openInstructionEndPoint(decoderStreamId);
         InstructionFlusher decoderInstructionFlusher = new InstructionFlusher(quicSession, decoderEndPoint, DecoderStreamConnection.STREAM_TYPE);
         decoder = new QpackDecoder(new InstructionHandler(decoderInstructionFlusher));
+        decoder.setMaxCompressionRatio(configuration.getMaxDecoderCompressionRatio());
         addBean(decoder);
         if (LOG.isDebugEnabled())
             LOG.debug("created decoder stream #{} on {}", decoderStreamId, decoderEndPoint);
@@ -142,6 +148,25 @@ public class ServerHTTP3Session extends ServerProtocolSession
             return v;
         });
 
+        settings.compute(SettingsFrame.ENABLE_CONNECT_PROTOCOL, (k, v) ->
+        {
+            if (v == null && configuration.isConnectProtocolEnabled())
+                v = 1L;
+            return v;
+        });
+        settings.compute(SettingsFrame.H3_DATAGRAM, (k, v) ->
+        {
+            if (v == null && configuration.isDatagramsEnabled())
+                v = 1L;
+            return v;
+        });
+        settings.compute(SettingsFrame.ENABLE_WEBTRANSPORT, (k, v) ->
+        {
+            if (v == null && configuration.isWebTransportEnabled())
+                v = 1L;
+            return v;
+        });
+
         if (LOG.isDebugEnabled())
             LOG.debug("configuring decoder {} on {}", settings, this);
 
@@ -185,6 +210,10 @@ public class ServerHTTP3Session extends ServerProtocolSession
                 int maxBlockedStreams = value.intValue();
                 encoder.setMaxBlockedStreams(maxBlockedStreams);
             }
+            else if (key == SettingsFrame.H3_DATAGRAM)
+            {
+                session.setRemoteDatagramsEnabled(value == 1);
+            }
         });
     }
 
@@ -225,6 +254,12 @@ public class ServerHTTP3Session extends ServerProtocolSession
         }
     }
 
+    @Override
+    protected void onDatagram(ByteBuffer datagram)
+    {
+        session.onDatagram(datagram);
+    }
+
     @Override
     protected boolean onIdleTimeout()
     {
@@ -13,11 +13,13 @@
 
 package org.eclipse.jetty.http3.server.internal;
 
+import java.nio.ByteBuffer;
 import java.util.function.Consumer;
 
 import org.eclipse.jetty.http3.frames.HeadersFrame;
 import org.eclipse.jetty.http3.internal.HTTP3Stream;
 import org.eclipse.jetty.http3.internal.HTTP3StreamConnection;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
 import org.eclipse.jetty.http3.internal.parser.MessageParser;
 import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
 import org.eclipse.jetty.server.Connector;
@@ -28,6 +30,7 @@ public class ServerHTTP3StreamConnection extends HTTP3StreamConnection
     private final Connector connector;
     private final HttpConfiguration httpConfiguration;
     private final ServerHTTP3Session session;
+    private volatile Consumer<ByteBuffer> datagramListener;
 
     public ServerHTTP3StreamConnection(Connector connector, HttpConfiguration httpConfiguration, QuicStreamEndPoint endPoint, ServerHTTP3Session session, MessageParser parser)
     {
@@ -53,25 +56,55 @@ public class ServerHTTP3StreamConnection extends HTTP3StreamConnection
 
     public Runnable onDataAvailable(HTTP3Stream stream)
     {
-        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)stream.getAttachment();
+        Object attachment = stream.getAttachment();
+        if (attachment instanceof HTTP3StreamEndPoint)
+            return ((HTTP3StreamEndPoint)attachment).onDataAvailable();
+        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)attachment;
         return channel.onDataAvailable();
     }
 
     public Runnable onTrailer(HTTP3Stream stream, HeadersFrame frame)
     {
-        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)stream.getAttachment();
+        Object attachment = stream.getAttachment();
+        // Tunnelled protocols do not have trailers.
+        if (attachment instanceof HTTP3StreamEndPoint)
+            return null;
+        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)attachment;
         return channel.onTrailer(frame);
     }
 
     public boolean onIdleTimeout(HTTP3Stream stream, Throwable failure, Consumer<Runnable> consumer)
     {
-        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)stream.getAttachment();
+        Object attachment = stream.getAttachment();
+        if (attachment instanceof HTTP3StreamEndPoint)
+            return ((HTTP3StreamEndPoint)attachment).onIdleTimeout(failure);
+        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)attachment;
         return channel.onIdleTimeout(failure, consumer);
     }
 
+    public void setDatagramListener(Consumer<ByteBuffer> listener)
+    {
+        this.datagramListener = listener;
+    }
+
+    public void onDatagram(HTTP3Stream stream, ByteBuffer payload)
+    {
+        // The listener survives tunnelling, so that tunnelled protocols
+        // such as UDP proxying can keep receiving datagrams.
+        Consumer<ByteBuffer> listener = datagramListener;
+        if (listener != null)
+            listener.accept(payload);
+    }
+
     public Runnable onFailure(HTTP3Stream stream, Throwable failure)
     {
-        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)stream.getAttachment();
+        Object attachment = stream.getAttachment();
+        if (attachment instanceof HTTP3StreamEndPoint)
+        {
+            HTTP3StreamEndPoint endPoint = (HTTP3StreamEndPoint)attachment;
+            return () -> endPoint.onFailure(failure);
+        }
+        HttpChannelOverHTTP3 channel = (HttpChannelOverHTTP3)attachment;
         return channel.onFailure(failure);
     }
 }
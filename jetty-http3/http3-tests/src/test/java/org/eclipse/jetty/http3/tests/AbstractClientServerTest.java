@@ -80,7 +80,7 @@ public class AbstractClientServerTest
         server.start();
     }
 
-    private void prepareServer(ConnectionFactory serverConnectionFactory)
+    protected void prepareServer(ConnectionFactory serverConnectionFactory)
     {
         SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
         sslContextFactory.setKeyStorePath("src/test/resources/keystore.p12");
@@ -616,4 +616,89 @@ public class ClientServerTest extends AbstractClientServerTest
         clientStream.data(new DataFrame(ByteBuffer.allocate(512), true));
         assertTrue(responseLatch.await(5, TimeUnit.SECONDS));
     }
+
+    @Test
+    public void testStreamStatistics() throws Exception
+    {
+        int requestLength = 1024;
+        int responseLength = 2048;
+        AtomicReference<Stream> serverStreamRef = new AtomicReference<>();
+        CountDownLatch serverLatch = new CountDownLatch(1);
+        start(new Session.Server.Listener()
+        {
+            @Override
+            public Stream.Server.Listener onRequest(Stream.Server stream, HeadersFrame frame)
+            {
+                serverStreamRef.set(stream);
+                stream.demand();
+                return new Stream.Server.Listener()
+                {
+                    @Override
+                    public void onDataAvailable(Stream.Server stream)
+                    {
+                        Stream.Data data = stream.readData();
+                        if (data == null)
+                        {
+                            stream.demand();
+                            return;
+                        }
+                        data.complete();
+                        if (!data.isLast())
+                        {
+                            stream.demand();
+                            return;
+                        }
+                        stream.respond(new HeadersFrame(new MetaData.Response(HttpVersion.HTTP_3, HttpStatus.OK_200, HttpFields.EMPTY), false))
+                            .thenCompose(s -> s.data(new DataFrame(ByteBuffer.allocate(responseLength), true)))
+                            .thenRun(serverLatch::countDown);
+                    }
+                };
+            }
+        });
+
+        Session.Client clientSession = newSession(new Session.Client.Listener() {});
+
+        CountDownLatch clientLatch = new CountDownLatch(1);
+        Stream clientStream = clientSession.newRequest(new HeadersFrame(newRequest(HttpMethod.POST, "/"), false), new Stream.Client.Listener()
+            {
+                @Override
+                public void onResponse(Stream.Client stream, HeadersFrame frame)
+                {
+                    stream.demand();
+                }
+
+                @Override
+                public void onDataAvailable(Stream.Client stream)
+                {
+                    Stream.Data data = stream.readData();
+                    if (data != null)
+                    {
+                        data.complete();
+                        if (data.isLast())
+                        {
+                            clientLatch.countDown();
+                            return;
+                        }
+                    }
+                    stream.demand();
+                }
+            })
+            .get(5, TimeUnit.SECONDS);
+        clientStream.data(new DataFrame(ByteBuffer.allocate(requestLength), true)).get(5, TimeUnit.SECONDS);
+
+        assertTrue(serverLatch.await(5, TimeUnit.SECONDS));
+        assertTrue(clientLatch.await(5, TimeUnit.SECONDS));
+
+        Stream.Statistics clientStatistics = clientStream.getStatistics();
+        assertEquals(2, clientStatistics.getFramesSent());
+        assertEquals(requestLength, clientStatistics.getBytesSent());
+        assertEquals(2, clientStatistics.getFramesReceived());
+        assertEquals(responseLength, clientStatistics.getBytesReceived());
+
+        Stream.Statistics serverStatistics = serverStreamRef.get().getStatistics();
+        assertEquals(2, serverStatistics.getFramesSent());
+        assertEquals(responseLength, serverStatistics.getBytesSent());
+        assertEquals(2, serverStatistics.getFramesReceived());
+        assertEquals(requestLength, serverStatistics.getBytesReceived());
+    }
 }
@@ -0,0 +1,192 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http3.tests;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.AsyncContext;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http3.api.Session;
+import org.eclipse.jetty.http3.api.Stream;
+import org.eclipse.jetty.http3.client.http.HttpClientDatagrams;
+import org.eclipse.jetty.http3.frames.HeadersFrame;
+import org.eclipse.jetty.http3.server.AbstractHTTP3ServerConnectionFactory;
+import org.eclipse.jetty.http3.server.HTTP3ServerConnectionFactory;
+import org.eclipse.jetty.http3.server.HttpDatagrams;
+import org.eclipse.jetty.http3.server.RawHTTP3ServerConnectionFactory;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class DatagramTest extends AbstractClientServerTest
+{
+    private void enableDatagrams()
+    {
+        connector.getQuicConfiguration().setDatagramsEnabled(true);
+        connector.getConnectionFactory(AbstractHTTP3ServerConnectionFactory.class).getHTTP3Configuration().setDatagramsEnabled(true);
+    }
+
+    private void startClientWithDatagrams() throws Exception
+    {
+        startClient();
+        http3Client.getQuicConfiguration().setDatagramsEnabled(true);
+        http3Client.getHTTP3Configuration().setDatagramsEnabled(true);
+    }
+
+    @Test
+    public void testDatagramEcho() throws Exception
+    {
+        prepareServer(new RawHTTP3ServerConnectionFactory(new Session.Server.Listener()
+        {
+            @Override
+            public Stream.Server.Listener onRequest(Stream.Server stream, HeadersFrame frame)
+            {
+                stream.respond(new HeadersFrame(new MetaData.Response(HttpVersion.HTTP_3, HttpStatus.OK_200, HttpFields.EMPTY), false));
+                return new Stream.Server.Listener()
+                {
+                    @Override
+                    public void onDatagram(Stream.Server stream, ByteBuffer payload)
+                    {
+                        stream.datagram(payload);
+                    }
+                };
+            }
+        }));
+        enableDatagrams();
+        server.start();
+        startClientWithDatagrams();
+
+        Session.Client session = newSession(new Session.Client.Listener() {});
+
+        CountDownLatch responseLatch = new CountDownLatch(1);
+        CountDownLatch datagramLatch = new CountDownLatch(1);
+        AtomicReference<String> echoRef = new AtomicReference<>();
+        Stream stream = session.newRequest(new HeadersFrame(newRequest("/"), false), new Stream.Client.Listener()
+            {
+                @Override
+                public void onResponse(Stream.Client stream, HeadersFrame frame)
+                {
+                    responseLatch.countDown();
+                }
+
+                @Override
+                public void onDatagram(Stream.Client stream, ByteBuffer payload)
+                {
+                    echoRef.set(BufferUtil.toString(payload, StandardCharsets.UTF_8));
+                    datagramLatch.countDown();
+                }
+            })
+            .get(5, TimeUnit.SECONDS);
+
+        assertTrue(responseLatch.await(5, TimeUnit.SECONDS));
+
+        stream.datagram(StandardCharsets.UTF_8.encode("hello")).get(5, TimeUnit.SECONDS);
+
+        assertTrue(datagramLatch.await(5, TimeUnit.SECONDS));
+        assertEquals("hello", echoRef.get());
+    }
+
+    @Test
+    public void testDatagramNotNegotiated() throws Exception
+    {
+        start(new Session.Server.Listener()
+        {
+            @Override
+            public Stream.Server.Listener onRequest(Stream.Server stream, HeadersFrame frame)
+            {
+                stream.respond(new HeadersFrame(new MetaData.Response(HttpVersion.HTTP_3, HttpStatus.OK_200, HttpFields.EMPTY), false));
+                return null;
+            }
+        });
+
+        Session.Client session = newSession(new Session.Client.Listener() {});
+
+        CountDownLatch responseLatch = new CountDownLatch(1);
+        Stream stream = session.newRequest(new HeadersFrame(newRequest("/"), false), new Stream.Client.Listener()
+            {
+                @Override
+                public void onResponse(Stream.Client stream, HeadersFrame frame)
+                {
+                    responseLatch.countDown();
+                }
+            })
+            .get(5, TimeUnit.SECONDS);
+
+        assertTrue(responseLatch.await(5, TimeUnit.SECONDS));
+
+        assertThrows(ExecutionException.class, () -> stream.datagram(StandardCharsets.UTF_8.encode("hello")).get(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testHttpClientDatagramEcho() throws Exception
+    {
+        prepareServer(new HTTP3ServerConnectionFactory());
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, org.eclipse.jetty.server.Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if (!HttpDatagrams.isEnabled(request))
+                {
+                    response.setStatus(HttpStatus.NOT_IMPLEMENTED_501);
+                    return;
+                }
+                AsyncContext asyncContext = request.startAsync();
+                HttpDatagrams.setListener(request, payload -> HttpDatagrams.send(request, payload)
+                    .whenComplete((r, x) -> asyncContext.complete()));
+                response.flushBuffer();
+            }
+        });
+        enableDatagrams();
+        server.start();
+        startClientWithDatagrams();
+
+        CountDownLatch datagramLatch = new CountDownLatch(1);
+        AtomicReference<String> echoRef = new AtomicReference<>();
+        Request request = httpClient.newRequest("https://localhost:" + connector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS);
+        HttpClientDatagrams.listener(request, payload ->
+        {
+            echoRef.set(BufferUtil.toString(payload, StandardCharsets.UTF_8));
+            datagramLatch.countDown();
+        });
+        request.onResponseHeaders(response -> HttpClientDatagrams.send(request, StandardCharsets.UTF_8.encode("hello")));
+
+        ContentResponse response = request.send();
+
+        assertNotNull(response);
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertTrue(datagramLatch.await(5, TimeUnit.SECONDS));
+        assertEquals("hello", echoRef.get());
+    }
+}
@@ -19,7 +19,14 @@ module org.eclipse.jetty.io
 
     // Only required if using JMX.
     requires static org.eclipse.jetty.jmx;
+    // Only required if using DNS over HTTPS.
+    requires static java.net.http;
 
     exports org.eclipse.jetty.io;
+    exports org.eclipse.jetty.io.dns;
     exports org.eclipse.jetty.io.ssl;
+
+    uses org.eclipse.jetty.io.ssl.ECHProcessor.Client;
+    uses org.eclipse.jetty.io.ssl.ECHProcessor.Server;
+    uses org.eclipse.jetty.io.TcpInfo.Provider;
 }
@@ -24,6 +24,7 @@ import java.util.function.IntConsumer;
 
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Pool;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.annotation.ManagedOperation;
@@ -44,6 +45,7 @@ abstract class AbstractByteBufferPool implements ByteBufferPool
     private final long _maxDirectMemory;
     private final AtomicLong _heapMemory = new AtomicLong();
     private final AtomicLong _directMemory = new AtomicLong();
+    private final Pool.StrategyType _retainedStrategyType;
     private final RetainableByteBufferPool _retainableByteBufferPool;
     
     /**
@@ -57,12 +59,29 @@ abstract class AbstractByteBufferPool implements ByteBufferPool
      * @param retainedDirectMemory the max direct memory in bytes, -2 for no retained memory, -1 for unlimited retained memory or 0 to use default heuristic
      */
     protected AbstractByteBufferPool(int factor, int maxCapacity, int maxBucketSize, long maxHeapMemory, long maxDirectMemory, long retainedHeapMemory, long retainedDirectMemory)
+    {
+        this(factor, maxCapacity, maxBucketSize, maxHeapMemory, maxDirectMemory, retainedHeapMemory, retainedDirectMemory, null);
+    }
+
+    /**
+     * Creates a new ByteBufferPool with the given configuration.
+     *
+     * @param factor the capacity factor
+     * @param maxBucketSize the maximum ByteBuffer queue length
+     * @param maxHeapMemory the max heap memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param maxDirectMemory the max direct memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param retainedHeapMemory the max heap memory in bytes, -2 for no retained memory, -1 for unlimited retained memory or 0 to use default heuristic
+     * @param retainedDirectMemory the max direct memory in bytes, -2 for no retained memory, -1 for unlimited retained memory or 0 to use default heuristic
+     * @param retainedStrategyType the strategy of the retained pool buckets, or null for the default strategy
+     */
+    protected AbstractByteBufferPool(int factor, int maxCapacity, int maxBucketSize, long maxHeapMemory, long maxDirectMemory, long retainedHeapMemory, long retainedDirectMemory, Pool.StrategyType retainedStrategyType)
     {
         _factor = factor <= 0 ? DEFAULT_FACTOR : factor;
         _maxCapacity = maxCapacity > 0 ? maxCapacity : DEFAULT_MAX_CAPACITY_BY_FACTOR * _factor;
         _maxBucketSize = maxBucketSize;
         _maxHeapMemory = memorySize(maxHeapMemory);
         _maxDirectMemory = memorySize(maxDirectMemory);
+        _retainedStrategyType = retainedStrategyType;
         _retainableByteBufferPool = (retainedHeapMemory == -2 && retainedDirectMemory == -2)
             ? RetainableByteBufferPool.from(this)
             : newRetainableByteBufferPool(factor, maxCapacity, maxBucketSize, retainedSize(retainedHeapMemory), retainedSize(retainedDirectMemory));
@@ -89,6 +108,15 @@ abstract class AbstractByteBufferPool implements ByteBufferPool
         return RetainableByteBufferPool.from(this);
     }
 
+    /**
+     * @return the strategy that {@link #newRetainableByteBufferPool(int, int, int, long, long)}
+     * should use for the retained pool buckets, or null for the default strategy
+     */
+    protected Pool.StrategyType getRetainedStrategyType()
+    {
+        return _retainedStrategyType;
+    }
+
     @Override
     public RetainableByteBufferPool asRetainableByteBufferPool()
     {
@@ -161,6 +161,10 @@ public abstract class AbstractConnection implements Connection
     {
         if (LOG.isDebugEnabled())
             LOG.debug("{} onFillInterestedFailed {}", this, cause);
+        for (Listener listener : _listeners)
+        {
+            onReadFailure(listener, cause);
+        }
         if (_endPoint.isOpen())
         {
             boolean close = true;
@@ -226,15 +230,27 @@ public abstract class AbstractConnection implements Connection
         }
         for (Listener listener : _listeners)
         {
-            onClosed(listener);
+            onClosed(listener, cause);
+        }
+    }
+
+    private void onReadFailure(Listener listener, Throwable failure)
+    {
+        try
+        {
+            listener.onReadFailure(this, failure);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
         }
     }
 
-    private void onClosed(Listener listener)
+    private void onClosed(Listener listener, Throwable cause)
     {
         try
         {
-            listener.onClosed(this);
+            listener.onClosed(this, cause);
         }
         catch (Throwable x)
         {
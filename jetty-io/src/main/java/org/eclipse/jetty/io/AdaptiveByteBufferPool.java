@@ -0,0 +1,714 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Iterator;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Queue;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.AtomicLongArray;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ByteBufferPool} whose buckets adapt to the sizes of the buffers that are actually acquired.</p>
+ * <p>Acquisitions are recorded in a histogram with a resolution of {@code minCapacity} bytes;
+ * periodically, the pool recomputes the capacities of at most {@code maxBuckets} buckets so that
+ * each bucket serves a similar share of the acquisitions, and retires the buckets whose capacity
+ * is no longer needed, discarding their buffers.
+ * The histogram decays at every adaptation, so that the bucket capacities follow the changes
+ * in the usage over time.</p>
+ * <p>The memory retained by pooled buffers is bounded by {@code maxHeapMemory} and
+ * {@code maxDirectMemory}: when a released buffer would exceed the budget, pooled buffers
+ * are evicted from the least recently used buckets.
+ * The memory budget default heuristic is to use {@link Runtime#maxMemory()} divided by 4.</p>
+ * <p>When {@link #setLeakTimeout(Duration) leak detection} is enabled, the stack trace of every
+ * acquisition is recorded, and buffers that are not released or removed within the leak timeout
+ * are reported via {@link #leaked(ByteBuffer, Throwable)}.</p>
+ * <p>Periodic adaptation and leak detection only happen while this pool is started;
+ * they can also be triggered explicitly via {@link #adapt()} and {@link #detectLeaks()}.</p>
+ */
+@ManagedObject
+public class AdaptiveByteBufferPool extends ContainerLifeCycle implements ByteBufferPool, Dumpable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AdaptiveByteBufferPool.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final Map<Identity, Throwable> _acquisitions = new ConcurrentHashMap<>();
+    private final LongAdder _leaks = new LongAdder();
+    private final RetainableByteBufferPool _retainableByteBufferPool = RetainableByteBufferPool.from(this);
+    private final int _minCapacity;
+    private final int _maxCapacity;
+    private final int _maxBuckets;
+    private final Partition _heap;
+    private final Partition _direct;
+    private Duration _adaptationPeriod = Duration.ofSeconds(30);
+    private volatile Duration _leakTimeout = Duration.ZERO;
+    private Scheduler _scheduler;
+    private Scheduler.Task _task;
+    private long _lastAdaptation = NanoTime.now();
+
+    /**
+     * Creates a new AdaptiveByteBufferPool with a default configuration.
+     */
+    public AdaptiveByteBufferPool()
+    {
+        this(-1, -1, -1, 0, 0);
+    }
+
+    /**
+     * Creates a new AdaptiveByteBufferPool with the given configuration.
+     *
+     * @param minCapacity the resolution in bytes of the bucket capacities, -1 for the default of 1024
+     * @param maxCapacity the max capacity of pooled buffers, which must be a multiple of {@code minCapacity}, -1 for the default of 65536
+     * @param maxBuckets the max number of buckets for heap and for direct buffers, -1 for the default of 16
+     * @param maxHeapMemory the max heap memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param maxDirectMemory the max direct memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     */
+    public AdaptiveByteBufferPool(int minCapacity, int maxCapacity, int maxBuckets, long maxHeapMemory, long maxDirectMemory)
+    {
+        _minCapacity = minCapacity > 0 ? minCapacity : 1024;
+        _maxCapacity = maxCapacity > 0 ? maxCapacity : 64 * 1024;
+        _maxBuckets = maxBuckets > 0 ? maxBuckets : 16;
+        if (_maxCapacity < _minCapacity || (_maxCapacity % _minCapacity) != 0)
+            throw new IllegalArgumentException("The max capacity must be a multiple of the min capacity");
+        _heap = new Partition(false, AbstractByteBufferPool.memorySize(maxHeapMemory));
+        _direct = new Partition(true, AbstractByteBufferPool.memorySize(maxDirectMemory));
+    }
+
+    @ManagedAttribute("The resolution in bytes of the bucket capacities")
+    public int getMinCapacity()
+    {
+        return _minCapacity;
+    }
+
+    @ManagedAttribute("The max capacity of pooled buffers")
+    public int getMaxCapacity()
+    {
+        return _maxCapacity;
+    }
+
+    @ManagedAttribute("The max number of buckets")
+    public int getMaxBuckets()
+    {
+        return _maxBuckets;
+    }
+
+    @ManagedAttribute("The period of bucket adaptation")
+    public Duration getAdaptationPeriod()
+    {
+        return _adaptationPeriod;
+    }
+
+    /**
+     * @param adaptationPeriod the period at which the buckets are adapted to the usage
+     */
+    public void setAdaptationPeriod(Duration adaptationPeriod)
+    {
+        if (adaptationPeriod.isNegative() || adaptationPeriod.isZero())
+            throw new IllegalArgumentException("Invalid adaptation period " + adaptationPeriod);
+        _adaptationPeriod = adaptationPeriod;
+    }
+
+    @ManagedAttribute("The timeout after which unreleased buffers are reported as leaked, zero to disable leak detection")
+    public Duration getLeakTimeout()
+    {
+        return _leakTimeout;
+    }
+
+    /**
+     * <p>Enables leak detection when the given timeout is positive.</p>
+     * <p>Leak detection records the stack trace of every acquisition
+     * and therefore it is costly; it should only be enabled to debug
+     * buffer leaks.</p>
+     *
+     * @param leakTimeout the timeout after which unreleased buffers are reported as leaked, zero to disable leak detection
+     */
+    public void setLeakTimeout(Duration leakTimeout)
+    {
+        if (leakTimeout.isNegative())
+            throw new IllegalArgumentException("Invalid leak timeout " + leakTimeout);
+        _leakTimeout = leakTimeout;
+        if (leakTimeout.isZero())
+            _acquisitions.clear();
+    }
+
+    public Scheduler getScheduler()
+    {
+        return _scheduler;
+    }
+
+    /**
+     * @param scheduler the scheduler of the periodic adaptation and leak detection,
+     * if null a scheduler is created when this pool is started
+     */
+    public void setScheduler(Scheduler scheduler)
+    {
+        if (isRunning())
+            throw new IllegalStateException(getState());
+        updateBean(_scheduler, scheduler);
+        _scheduler = scheduler;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_scheduler == null)
+        {
+            _scheduler = new ScheduledExecutorScheduler(String.format("AdaptiveByteBufferPool@%x-scheduler", hashCode()), true);
+            addManaged(_scheduler);
+        }
+        super.doStart();
+        schedule();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (_task != null)
+                _task.cancel();
+            _task = null;
+        }
+        super.doStop();
+    }
+
+    private void schedule()
+    {
+        long period = _adaptationPeriod.toNanos();
+        Duration leakTimeout = _leakTimeout;
+        if (!leakTimeout.isZero())
+            period = Math.min(period, leakTimeout.toNanos());
+        try (AutoLock l = _lock.lock())
+        {
+            if (isRunning())
+                _task = _scheduler.schedule(this::sweep, period, TimeUnit.NANOSECONDS);
+        }
+    }
+
+    private void sweep()
+    {
+        try
+        {
+            boolean adapt;
+            try (AutoLock l = _lock.lock())
+            {
+                long now = NanoTime.now();
+                adapt = NanoTime.elapsed(_lastAdaptation, now) >= _adaptationPeriod.toNanos();
+                if (adapt)
+                    _lastAdaptation = now;
+            }
+            if (adapt)
+                adapt();
+            detectLeaks();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Could not sweep {}", this, x);
+        }
+        finally
+        {
+            schedule();
+        }
+    }
+
+    @Override
+    public RetainableByteBufferPool asRetainableByteBufferPool()
+    {
+        return _retainableByteBufferPool;
+    }
+
+    @Override
+    public ByteBuffer acquire(int size, boolean direct)
+    {
+        ByteBuffer buffer = partitionFor(direct).acquire(size);
+        if (!_leakTimeout.isZero())
+            _acquisitions.put(new Identity(buffer), new Throwable("Acquisition"));
+        return buffer;
+    }
+
+    @Override
+    public void release(ByteBuffer buffer)
+    {
+        if (buffer == null)
+            return;
+        if (!_acquisitions.isEmpty())
+            _acquisitions.remove(new Identity(buffer));
+        partitionFor(buffer.isDirect()).release(buffer);
+    }
+
+    @Override
+    public void remove(ByteBuffer buffer)
+    {
+        if (buffer == null)
+            return;
+        if (!_acquisitions.isEmpty())
+            _acquisitions.remove(new Identity(buffer));
+    }
+
+    private Partition partitionFor(boolean direct)
+    {
+        return direct ? _direct : _heap;
+    }
+
+    /**
+     * <p>Adapts the bucket capacities to the acquisitions recorded since
+     * the previous adaptations, retiring the buckets that are not needed.</p>
+     */
+    @ManagedOperation(value = "Adapts the buckets to the usage", impact = "ACTION")
+    public void adapt()
+    {
+        _heap.adapt();
+        _direct.adapt();
+    }
+
+    /**
+     * <p>Reports the buffers that have been acquired and not released
+     * or removed within the {@link #getLeakTimeout() leak timeout}.</p>
+     * <p>Leaked buffers are reported only once, and then no longer tracked.</p>
+     */
+    @ManagedOperation(value = "Reports the leaked buffers", impact = "ACTION")
+    public void detectLeaks()
+    {
+        Duration leakTimeout = _leakTimeout;
+        if (leakTimeout.isZero())
+            return;
+        long now = NanoTime.now();
+        for (Iterator<Map.Entry<Identity, Throwable>> iterator = _acquisitions.entrySet().iterator(); iterator.hasNext();)
+        {
+            Map.Entry<Identity, Throwable> entry = iterator.next();
+            if (NanoTime.elapsed(entry.getKey()._nanoTime, now) < leakTimeout.toNanos())
+                continue;
+            iterator.remove();
+            _leaks.increment();
+            leaked(entry.getKey()._buffer, entry.getValue());
+        }
+    }
+
+    /**
+     * <p>Invoked when a buffer has not been released or removed within the leak timeout.</p>
+     *
+     * @param buffer the leaked buffer
+     * @param acquisition a throwable whose stack trace is the stack trace of the acquisition
+     */
+    protected void leaked(ByteBuffer buffer, Throwable acquisition)
+    {
+        LOG.warn("ByteBuffer {} not released within {} ms", BufferUtil.toIDString(buffer), _leakTimeout.toMillis(), acquisition);
+    }
+
+    @ManagedAttribute("The number of buffers reported as leaked")
+    public long getLeakedBuffers()
+    {
+        return _leaks.sum();
+    }
+
+    @ManagedAttribute("The number of acquired buffers tracked by leak detection")
+    public int getTrackedBuffers()
+    {
+        return _acquisitions.size();
+    }
+
+    /**
+     * @param direct whether to return the capacities of the direct buckets or of the heap buckets
+     * @return the capacities of the buckets, in ascending order
+     */
+    public List<Integer> getBucketCapacities(boolean direct)
+    {
+        return Arrays.stream(partitionFor(direct)._buckets)
+            .map(bucket -> bucket._capacity)
+            .collect(Collectors.toList());
+    }
+
+    @ManagedAttribute("The bytes retained by direct ByteBuffers")
+    public long getDirectMemory()
+    {
+        return _direct._memory.get();
+    }
+
+    @ManagedAttribute("The bytes retained by heap ByteBuffers")
+    public long getHeapMemory()
+    {
+        return _heap._memory.get();
+    }
+
+    @ManagedAttribute("The max num of bytes that can be retained from direct ByteBuffers")
+    public long getMaxDirectMemory()
+    {
+        return _direct._maxMemory;
+    }
+
+    @ManagedAttribute("The max num of bytes that can be retained from heap ByteBuffers")
+    public long getMaxHeapMemory()
+    {
+        return _heap._maxMemory;
+    }
+
+    @ManagedAttribute("The number of acquisitions served by pooled buffers")
+    public long getHits()
+    {
+        return _heap._hits.sum() + _direct._hits.sum();
+    }
+
+    @ManagedAttribute("The number of acquisitions that allocated a new buffer")
+    public long getMisses()
+    {
+        return _heap._misses.sum() + _direct._misses.sum();
+    }
+
+    @ManagedAttribute("The number of pooled buffers evicted to honor the memory budget")
+    public long getEvictions()
+    {
+        return _heap._evictions.sum() + _direct._evictions.sum();
+    }
+
+    @ManagedOperation(value = "Clears this ByteBufferPool", impact = "ACTION")
+    public void clear()
+    {
+        _heap.clear();
+        _direct.clear();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _heap.resetStatistics();
+        _direct.resetStatistics();
+        _leaks.reset();
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        Dumpable.dumpObjects(out, indent, this, _heap, _direct);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{minCapacity=%d,maxCapacity=%d,maxBuckets=%d,leakTimeout=%s}",
+            getClass().getSimpleName(),
+            hashCode(),
+            _minCapacity,
+            _maxCapacity,
+            _maxBuckets,
+            _leakTimeout);
+    }
+
+    private class Partition
+    {
+        private final LongAdder _hits = new LongAdder();
+        private final LongAdder _misses = new LongAdder();
+        private final LongAdder _evictions = new LongAdder();
+        private final AtomicLong _memory = new AtomicLong();
+        // A logical clock to order the bucket usage for eviction.
+        private final AtomicLong _clock = new AtomicLong();
+        private final AtomicLongArray _histogram = new AtomicLongArray(_maxCapacity / _minCapacity);
+        private final boolean _isDirect;
+        private final long _maxMemory;
+        private volatile Bucket[] _buckets;
+
+        private Partition(boolean direct, long maxMemory)
+        {
+            _isDirect = direct;
+            _maxMemory = maxMemory;
+            // Until there is usage information, use power of two capacities.
+            List<Bucket> buckets = new ArrayList<>();
+            for (int capacity = _maxCapacity; capacity >= _minCapacity && buckets.size() < _maxBuckets; capacity /= 2)
+            {
+                if (capacity % _minCapacity != 0)
+                    break;
+                buckets.add(0, new Bucket(capacity));
+            }
+            _buckets = buckets.toArray(new Bucket[0]);
+        }
+
+        private ByteBuffer acquire(int size)
+        {
+            if (size > _maxCapacity)
+            {
+                _misses.increment();
+                return newByteBuffer(size, _isDirect);
+            }
+
+            _histogram.incrementAndGet(Math.max(0, size - 1) / _minCapacity);
+
+            Bucket bucket = null;
+            for (Bucket b : _buckets)
+            {
+                if (b._capacity >= size)
+                {
+                    bucket = b;
+                    break;
+                }
+            }
+            if (bucket == null)
+            {
+                // The buckets have adapted to smaller sizes.
+                _misses.increment();
+                return newByteBuffer(roundUp(size), _isDirect);
+            }
+
+            ByteBuffer buffer = bucket.poll(this);
+            if (buffer == null)
+            {
+                _misses.increment();
+                return newByteBuffer(bucket._capacity, _isDirect);
+            }
+            _hits.increment();
+            return buffer;
+        }
+
+        private int roundUp(int size)
+        {
+            return Math.max(1, (size + _minCapacity - 1) / _minCapacity) * _minCapacity;
+        }
+
+        private void release(ByteBuffer buffer)
+        {
+            int capacity = buffer.capacity();
+            Bucket bucket = null;
+            for (Bucket b : _buckets)
+            {
+                if (b._capacity == capacity)
+                {
+                    bucket = b;
+                    break;
+                }
+            }
+            // The buffer does not belong to this pool, or its bucket has been retired.
+            if (bucket == null)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Discarding {} from {}", BufferUtil.toDetailString(buffer), AdaptiveByteBufferPool.this);
+                return;
+            }
+
+            if (_maxMemory > 0)
+            {
+                while (_memory.get() + capacity > _maxMemory)
+                {
+                    if (!evict())
+                        return;
+                }
+            }
+
+            BufferUtil.reset(buffer);
+            bucket.offer(this, buffer);
+        }
+
+        private boolean evict()
+        {
+            Bucket oldest = null;
+            for (Bucket bucket : _buckets)
+            {
+                if (bucket.isEmpty())
+                    continue;
+                if (oldest == null || bucket._lastUse < oldest._lastUse)
+                    oldest = bucket;
+            }
+            if (oldest == null)
+                return false;
+            if (oldest.poll(this) != null)
+                _evictions.increment();
+            return true;
+        }
+
+        private void adapt()
+        {
+            long[] counts = new long[_histogram.length()];
+            long total = 0;
+            for (int i = 0; i < counts.length; ++i)
+            {
+                // Halve the counts, so that older acquisitions weigh less.
+                counts[i] = _histogram.getAndUpdate(i, count -> count / 2);
+                total += counts[i];
+            }
+            if (total == 0)
+                return;
+
+            // Each bucket covers a similar share of the acquisitions.
+            List<Integer> capacities = new ArrayList<>();
+            long share = (total + _maxBuckets - 1) / _maxBuckets;
+            long threshold = share;
+            long cumulative = 0;
+            for (int i = 0; i < counts.length; ++i)
+            {
+                if (counts[i] == 0)
+                    continue;
+                cumulative += counts[i];
+                if (cumulative >= threshold || cumulative == total)
+                {
+                    capacities.add((i + 1) * _minCapacity);
+                    while (threshold <= cumulative)
+                    {
+                        threshold += share;
+                    }
+                }
+            }
+
+            Bucket[] oldBuckets = _buckets;
+            Bucket[] newBuckets = new Bucket[capacities.size()];
+            for (int i = 0; i < newBuckets.length; ++i)
+            {
+                int capacity = capacities.get(i);
+                newBuckets[i] = Arrays.stream(oldBuckets)
+                    .filter(bucket -> bucket._capacity == capacity)
+                    .findFirst()
+                    .orElseGet(() -> new Bucket(capacity));
+            }
+            _buckets = newBuckets;
+
+            for (Bucket bucket : oldBuckets)
+            {
+                if (!Arrays.asList(newBuckets).contains(bucket))
+                    bucket.retire(this);
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Adapted {} buckets {} -> {}", _isDirect ? "direct" : "heap", Arrays.asList(oldBuckets), Arrays.asList(newBuckets));
+        }
+
+        private void clear()
+        {
+            for (Bucket bucket : _buckets)
+            {
+                bucket.clear(this);
+            }
+        }
+
+        private void resetStatistics()
+        {
+            _hits.reset();
+            _misses.reset();
+            _evictions.reset();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s{memory=%d/%d,buckets=%s}", _isDirect ? "direct" : "heap", _memory.get(), _maxMemory, Arrays.asList(_buckets));
+        }
+    }
+
+    private static class Bucket
+    {
+        private final Queue<ByteBuffer> _queue = new ConcurrentLinkedQueue<>();
+        private final AtomicInteger _size = new AtomicInteger();
+        private final int _capacity;
+        private volatile long _lastUse;
+        private volatile boolean _retired;
+
+        private Bucket(int capacity)
+        {
+            _capacity = capacity;
+        }
+
+        private ByteBuffer poll(Partition partition)
+        {
+            ByteBuffer buffer = _queue.poll();
+            if (buffer != null)
+            {
+                _size.decrementAndGet();
+                partition._memory.addAndGet(-buffer.capacity());
+                _lastUse = partition._clock.incrementAndGet();
+            }
+            return buffer;
+        }
+
+        private void offer(Partition partition, ByteBuffer buffer)
+        {
+            partition._memory.addAndGet(buffer.capacity());
+            _size.incrementAndGet();
+            _queue.offer(buffer);
+            _lastUse = partition._clock.incrementAndGet();
+            // Lost a race with retire().
+            if (_retired)
+                clear(partition);
+        }
+
+        private void retire(Partition partition)
+        {
+            _retired = true;
+            clear(partition);
+        }
+
+        private void clear(Partition partition)
+        {
+            while (true)
+            {
+                if (poll(partition) == null)
+                    break;
+            }
+        }
+
+        private boolean isEmpty()
+        {
+            return _queue.isEmpty();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%d:%d", _capacity, _size.get());
+        }
+    }
+
+    private static class Identity
+    {
+        private final ByteBuffer _buffer;
+        private final long _nanoTime = NanoTime.now();
+
+        private Identity(ByteBuffer buffer)
+        {
+            _buffer = Objects.requireNonNull(buffer);
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            return obj instanceof Identity && ((Identity)obj)._buffer == _buffer;
+        }
+
+        @Override
+        public int hashCode()
+        {
+            return System.identityHashCode(_buffer);
+        }
+    }
+}
@@ -95,6 +95,22 @@ public class ArrayRetainableByteBufferPool implements RetainableByteBufferPool,
         this(minCapacity, factor, maxCapacity, maxBucketSize, null, null, maxHeapMemory, maxDirectMemory);
     }
 
+    /**
+     * Creates a new ArrayRetainableByteBufferPool with the given configuration.
+     *
+     * @param minCapacity the minimum ByteBuffer capacity
+     * @param factor the capacity factor
+     * @param maxCapacity the maximum ByteBuffer capacity
+     * @param maxBucketSize the maximum number of ByteBuffers for each bucket
+     * @param maxHeapMemory the max heap memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param maxDirectMemory the max direct memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param strategyType the strategy used by each bucket to look up ByteBuffers, or null for the default strategy
+     */
+    public ArrayRetainableByteBufferPool(int minCapacity, int factor, int maxCapacity, int maxBucketSize, long maxHeapMemory, long maxDirectMemory, Pool.StrategyType strategyType)
+    {
+        this(minCapacity, factor, maxCapacity, maxBucketSize, null, null, maxHeapMemory, maxDirectMemory, strategyType);
+    }
+
     /**
      * Creates a new ArrayRetainableByteBufferPool with the given configuration.
      *
@@ -130,6 +146,26 @@ public class ArrayRetainableByteBufferPool implements RetainableByteBufferPool,
      */
     protected ArrayRetainableByteBufferPool(int minCapacity, int factor, int maxCapacity, int maxBucketSize, IntUnaryOperator bucketIndexFor, IntUnaryOperator bucketCapacity, long maxHeapMemory, long maxDirectMemory)
     {
+        this(minCapacity, factor, maxCapacity, maxBucketSize, bucketIndexFor, bucketCapacity, maxHeapMemory, maxDirectMemory, Pool.StrategyType.THREAD_ID);
+    }
+
+    /**
+     * Creates a new ArrayRetainableByteBufferPool with the given configuration.
+     *
+     * @param minCapacity the minimum ByteBuffer capacity
+     * @param factor the capacity factor
+     * @param maxCapacity the maximum ByteBuffer capacity
+     * @param maxBucketSize the maximum number of ByteBuffers for each bucket
+     * @param bucketIndexFor a {@link IntUnaryOperator} that takes a capacity and returns a bucket index
+     * @param bucketCapacity a {@link IntUnaryOperator} that takes a bucket index and returns a capacity
+     * @param maxHeapMemory the max heap memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param maxDirectMemory the max direct memory in bytes, -1 for unlimited memory or 0 to use default heuristic
+     * @param strategyType the strategy used by each bucket to look up ByteBuffers, or null for the default strategy
+     */
+    protected ArrayRetainableByteBufferPool(int minCapacity, int factor, int maxCapacity, int maxBucketSize, IntUnaryOperator bucketIndexFor, IntUnaryOperator bucketCapacity, long maxHeapMemory, long maxDirectMemory, Pool.StrategyType strategyType)
+    {
+        if (strategyType == null)
+            strategyType = Pool.StrategyType.THREAD_ID;
         if (minCapacity <= 0)
             minCapacity = 0;
         factor = factor <= 0 ? AbstractByteBufferPool.DEFAULT_FACTOR : factor;
@@ -150,8 +186,8 @@ public class ArrayRetainableByteBufferPool implements RetainableByteBufferPool,
         for (int i = 0; i < directArray.length; i++)
         {
             int capacity = Math.min(bucketCapacity.applyAsInt(i), maxCapacity);
-            directArray[i] = new RetainedBucket(capacity, maxBucketSize);
-            indirectArray[i] = new RetainedBucket(capacity, maxBucketSize);
+            directArray[i] = new RetainedBucket(capacity, maxBucketSize, strategyType);
+            indirectArray[i] = new RetainedBucket(capacity, maxBucketSize, strategyType);
         }
 
         _minCapacity = minCapacity;
@@ -461,9 +497,9 @@ public class ArrayRetainableByteBufferPool implements RetainableByteBufferPool,
     {
         private final int _capacity;
 
-        RetainedBucket(int capacity, int size)
+        RetainedBucket(int capacity, int size, Pool.StrategyType strategyType)
         {
-            super(Pool.StrategyType.THREAD_ID, size, true);
+            super(strategyType, size, true);
             _capacity = capacity;
         }
 
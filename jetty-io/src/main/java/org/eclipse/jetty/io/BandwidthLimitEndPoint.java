@@ -0,0 +1,394 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.nio.channels.ReadPendingException;
+import java.nio.channels.WritePendingException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicReference;
+
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.thread.Invocable;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link EndPoint} that wraps another {@link EndPoint} and limits
+ * the read and write throughput using {@link BandwidthLimiter}s.</p>
+ * <p>Since it works at the {@link EndPoint} level, it limits the throughput
+ * independently of the protocol spoken over the connection, for example
+ * HTTP/1.1, HTTP/2 or WebSocket, or TLS if the wrapped {@link EndPoint}
+ * is the network {@link EndPoint}.</p>
+ * <p>Reads are limited by not filling bytes until the read limiter allows,
+ * while writes are split in chunks whose size is allowed by the write limiter.</p>
+ */
+public class BandwidthLimitEndPoint implements EndPoint, EndPoint.Wrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(BandwidthLimitEndPoint.class);
+
+    private final AtomicReference<Callback> _fillInterest = new AtomicReference<>();
+    private final AtomicBoolean _writing = new AtomicBoolean();
+    private final EndPoint _endPoint;
+    private final Scheduler _scheduler;
+    private final BandwidthLimiter _readLimiter;
+    private final BandwidthLimiter _writeLimiter;
+
+    /**
+     * @param endPoint the {@link EndPoint} to wrap
+     * @param scheduler the scheduler to delay reads and writes
+     * @param readLimiter the limiter for reads, or null to not limit reads
+     * @param writeLimiter the limiter for writes, or null to not limit writes
+     */
+    public BandwidthLimitEndPoint(EndPoint endPoint, Scheduler scheduler, BandwidthLimiter readLimiter, BandwidthLimiter writeLimiter)
+    {
+        _endPoint = endPoint;
+        _scheduler = scheduler;
+        _readLimiter = readLimiter;
+        _writeLimiter = writeLimiter;
+    }
+
+    @Override
+    public EndPoint unwrap()
+    {
+        return _endPoint;
+    }
+
+    /**
+     * @return the limiter for reads, or null if reads are not limited
+     */
+    public BandwidthLimiter getReadLimiter()
+    {
+        return _readLimiter;
+    }
+
+    /**
+     * @return the limiter for writes, or null if writes are not limited
+     */
+    public BandwidthLimiter getWriteLimiter()
+    {
+        return _writeLimiter;
+    }
+
+    @Override
+    public int fill(ByteBuffer buffer) throws IOException
+    {
+        if (_readLimiter == null)
+            return _endPoint.fill(buffer);
+        if (_readLimiter.getDelay() > 0)
+            return 0;
+        int filled = _endPoint.fill(buffer);
+        if (filled > 0)
+            _readLimiter.reserve(filled);
+        return filled;
+    }
+
+    @Override
+    public void fillInterested(Callback callback) throws ReadPendingException
+    {
+        if (!tryFillInterested(callback))
+            throw new ReadPendingException();
+    }
+
+    @Override
+    public boolean tryFillInterested(Callback callback)
+    {
+        long delay = _readLimiter == null ? 0 : _readLimiter.getDelay();
+        if (delay == 0)
+            return _fillInterest.get() == null && _endPoint.tryFillInterested(callback);
+        if (!_fillInterest.compareAndSet(null, callback))
+            return false;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Delaying fill interest by {} ns on {}", delay, this);
+        _scheduler.schedule(this::onFillDelayExpired, delay, TimeUnit.NANOSECONDS);
+        return true;
+    }
+
+    private void onFillDelayExpired()
+    {
+        Callback callback = _fillInterest.getAndSet(null);
+        if (callback == null)
+            return;
+        if (!_endPoint.isOpen())
+        {
+            callback.failed(new ClosedChannelException());
+            return;
+        }
+        try
+        {
+            _endPoint.fillInterested(callback);
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+        }
+    }
+
+    @Override
+    public boolean isFillInterested()
+    {
+        return _fillInterest.get() != null || _endPoint.isFillInterested();
+    }
+
+    @Override
+    public boolean flush(ByteBuffer... buffers) throws IOException
+    {
+        if (_writeLimiter == null)
+            return _endPoint.flush(buffers);
+        long remaining = BufferUtil.remaining(buffers);
+        if (remaining > 0 && _writeLimiter.getDelay() > 0)
+            return false;
+        boolean flushed = _endPoint.flush(buffers);
+        long written = remaining - BufferUtil.remaining(buffers);
+        if (written > 0)
+            _writeLimiter.reserve(written);
+        return flushed;
+    }
+
+    @Override
+    public void write(Callback callback, ByteBuffer... buffers) throws WritePendingException
+    {
+        if (_writeLimiter == null)
+        {
+            _endPoint.write(callback, buffers);
+            return;
+        }
+        if (!_writing.compareAndSet(false, true))
+            throw new WritePendingException();
+        new Writer(callback, buffers).iterate();
+    }
+
+    @Override
+    public InetSocketAddress getLocalAddress()
+    {
+        return _endPoint.getLocalAddress();
+    }
+
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return _endPoint.getLocalSocketAddress();
+    }
+
+    @Override
+    public InetSocketAddress getRemoteAddress()
+    {
+        return _endPoint.getRemoteAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return _endPoint.getRemoteSocketAddress();
+    }
+
+    @Override
+    public boolean isOpen()
+    {
+        return _endPoint.isOpen();
+    }
+
+    @Override
+    public long getCreatedTimeStamp()
+    {
+        return _endPoint.getCreatedTimeStamp();
+    }
+
+    @Override
+    public void shutdownOutput()
+    {
+        _endPoint.shutdownOutput();
+    }
+
+    @Override
+    public boolean isOutputShutdown()
+    {
+        return _endPoint.isOutputShutdown();
+    }
+
+    @Override
+    public boolean isInputShutdown()
+    {
+        return _endPoint.isInputShutdown();
+    }
+
+    @Override
+    public void close(Throwable cause)
+    {
+        _endPoint.close(cause);
+    }
+
+    @Override
+    public Object getTransport()
+    {
+        return _endPoint.getTransport();
+    }
+
+    @Override
+    public long getIdleTimeout()
+    {
+        return _endPoint.getIdleTimeout();
+    }
+
+    @Override
+    public void setIdleTimeout(long idleTimeout)
+    {
+        _endPoint.setIdleTimeout(idleTimeout);
+    }
+
+    @Override
+    public Connection getConnection()
+    {
+        return _endPoint.getConnection();
+    }
+
+    @Override
+    public void setConnection(Connection connection)
+    {
+        _endPoint.setConnection(connection);
+    }
+
+    @Override
+    public void onOpen()
+    {
+        _endPoint.onOpen();
+    }
+
+    @Override
+    public void onClose(Throwable cause)
+    {
+        _endPoint.onClose(cause);
+    }
+
+    @Override
+    public void upgrade(Connection newConnection)
+    {
+        _endPoint.upgrade(newConnection);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[read=%s,write=%s,endpoint=%s]",
+            getClass().getSimpleName(),
+            hashCode(),
+            _readLimiter,
+            _writeLimiter,
+            _endPoint);
+    }
+
+    private class Writer extends IteratingCallback
+    {
+        private final Callback _callback;
+        private final ByteBuffer[] _buffers;
+        private int _chunk;
+
+        private Writer(Callback callback, ByteBuffer[] buffers)
+        {
+            _callback = callback;
+            _buffers = buffers;
+        }
+
+        @Override
+        protected Action process()
+        {
+            if (_chunk > 0)
+            {
+                // The previous chunk has been written.
+                advance(_chunk);
+                _chunk = 0;
+            }
+
+            long remaining = BufferUtil.remaining(_buffers);
+            if (remaining == 0)
+                return Action.SUCCEEDED;
+
+            long delay = _writeLimiter.getDelay();
+            if (delay > 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Delaying write by {} ns on {}", delay, BandwidthLimitEndPoint.this);
+                _scheduler.schedule(this::succeeded, delay, TimeUnit.NANOSECONDS);
+                return Action.SCHEDULED;
+            }
+
+            int length = (int)Math.min(Integer.MAX_VALUE, Math.min(remaining, Math.max(1, _writeLimiter.getAvailable())));
+            _writeLimiter.reserve(length);
+            _chunk = length;
+            _endPoint.write(this, slice(length));
+            return Action.SCHEDULED;
+        }
+
+        private ByteBuffer[] slice(int length)
+        {
+            List<ByteBuffer> slices = new ArrayList<>(_buffers.length);
+            for (ByteBuffer buffer : _buffers)
+            {
+                if (length == 0)
+                    break;
+                int remaining = buffer.remaining();
+                if (remaining == 0)
+                    continue;
+                ByteBuffer slice = buffer.slice();
+                if (remaining > length)
+                    slice.limit(length);
+                slices.add(slice);
+                length -= slice.remaining();
+            }
+            return slices.toArray(new ByteBuffer[0]);
+        }
+
+        private void advance(int length)
+        {
+            for (ByteBuffer buffer : _buffers)
+            {
+                if (length == 0)
+                    break;
+                int advance = Math.min(length, buffer.remaining());
+                buffer.position(buffer.position() + advance);
+                length -= advance;
+            }
+        }
+
+        @Override
+        protected void onCompleteSuccess()
+        {
+            _writing.set(false);
+            _callback.succeeded();
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable cause)
+        {
+            _writing.set(false);
+            _callback.failed(cause);
+        }
+
+        @Override
+        public InvocationType getInvocationType()
+        {
+            return Invocable.getInvocationType(_callback);
+        }
+    }
+}
@@ -0,0 +1,141 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A token bucket that limits the rate, in bytes per second, of a data transfer.</p>
+ * <p>The bucket holds up to {@link #getBurst() burst} bytes, and refills at
+ * the configured {@link #getRate() rate}.
+ * Bytes are {@link #reserve(long) reserved} when they are transferred, possibly
+ * in excess of the bytes {@link #getAvailable() available} in the bucket, in which
+ * case the bucket goes into debt: the transfer must wait the {@link #getDelay() delay}
+ * necessary to repay the debt before transferring more bytes.</p>
+ * <p>A {@code BandwidthLimiter} may be shared, for example by multiple connections
+ * to limit their aggregate rate.</p>
+ */
+public class BandwidthLimiter
+{
+    private static final long NANOS_PER_SECOND = TimeUnit.SECONDS.toNanos(1);
+
+    private final AutoLock _lock = new AutoLock();
+    private final long _rate;
+    private final long _burst;
+    private long _tokens;
+    private long _nanoTime;
+
+    /**
+     * @param rate the rate in bytes per second
+     * @param burst the max number of bytes that can be transferred at once
+     */
+    public BandwidthLimiter(long rate, long burst)
+    {
+        if (rate <= 0)
+            throw new IllegalArgumentException("Invalid rate " + rate);
+        if (burst <= 0)
+            throw new IllegalArgumentException("Invalid burst " + burst);
+        _rate = rate;
+        _burst = burst;
+        _tokens = burst;
+        _nanoTime = NanoTime.now();
+    }
+
+    /**
+     * @return the rate in bytes per second
+     */
+    public long getRate()
+    {
+        return _rate;
+    }
+
+    /**
+     * @return the max number of bytes that can be transferred at once
+     */
+    public long getBurst()
+    {
+        return _burst;
+    }
+
+    /**
+     * @return the number of bytes that can be transferred without waiting
+     */
+    public long getAvailable()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            refill(NanoTime.now());
+            return Math.max(0, _tokens);
+        }
+    }
+
+    /**
+     * <p>Reserves the given number of bytes, possibly putting the bucket into debt.</p>
+     *
+     * @param bytes the number of bytes transferred
+     */
+    public void reserve(long bytes)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            long now = NanoTime.now();
+            refill(now);
+            _tokens -= bytes;
+        }
+    }
+
+    /**
+     * @return the time in nanoseconds to wait before transferring more bytes, or 0 if bytes can be transferred now
+     */
+    public long getDelay()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            long now = NanoTime.now();
+            refill(now);
+            if (_tokens > 0)
+                return 0;
+            long nanos = (long)Math.ceil((1 - _tokens) * (double)NANOS_PER_SECOND / _rate);
+            return Math.max(1, nanos - NanoTime.elapsed(_nanoTime, now));
+        }
+    }
+
+    private void refill(long now)
+    {
+        long elapsed = NanoTime.elapsed(_nanoTime, now);
+        long tokens = (long)(elapsed * (double)_rate / NANOS_PER_SECOND);
+        if (tokens <= 0)
+            return;
+        _tokens += tokens;
+        if (_tokens >= _burst)
+        {
+            _tokens = _burst;
+            _nanoTime = now;
+        }
+        else
+        {
+            // Keep the fraction of time not yet converted into tokens.
+            _nanoTime += (long)(tokens * (double)NANOS_PER_SECOND / _rate);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[rate=%d/s,burst=%d]", getClass().getSimpleName(), hashCode(), _rate, _burst);
+    }
+}
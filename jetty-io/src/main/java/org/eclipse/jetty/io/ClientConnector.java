@@ -78,6 +78,11 @@ public class ClientConnector extends ContainerLifeCycle
     public static final String CLIENT_CONNECTION_FACTORY_CONTEXT_KEY = CLIENT_CONNECTOR_CONTEXT_KEY + ".clientConnectionFactory";
     public static final String CONNECTION_PROMISE_CONTEXT_KEY = CLIENT_CONNECTOR_CONTEXT_KEY + ".connectionPromise";
     public static final String APPLICATION_PROTOCOLS_CONTEXT_KEY = CLIENT_CONNECTOR_CONTEXT_KEY + ".applicationProtocols";
+    /**
+     * <p>The context key of the Unix-Domain {@link Path} to connect to, overriding
+     * the socket address passed to {@link #connect(SocketAddress, Map)}.</p>
+     */
+    public static final String UNIX_DOMAIN_PATH_CONTEXT_KEY = CLIENT_CONNECTOR_CONTEXT_KEY + ".unixDomainPath";
     private static final Logger LOG = LoggerFactory.getLogger(ClientConnector.class);
 
     /**
@@ -619,6 +624,8 @@ public class ClientConnector extends ContainerLifeCycle
          * <p>However, the returned socket address may be different as the implementation
          * may use a Unix-Domain socket address to physically connect to the virtual
          * destination socket address given as input.</p>
+         * <p>The default implementation connects to the Unix-Domain path specified
+         * in the context under the {@link ClientConnector#UNIX_DOMAIN_PATH_CONTEXT_KEY} key, if present.</p>
          * <p>The return type is a pair/record holding the socket channel and the
          * socket address, with the socket channel not yet connected.
          * The implementation of this methods must not call
@@ -633,6 +640,9 @@ public class ClientConnector extends ContainerLifeCycle
          */
         public ChannelWithAddress newChannelWithAddress(ClientConnector clientConnector, SocketAddress address, Map<String, Object> context) throws IOException
         {
+            Path unixDomainPath = (Path)context.get(UNIX_DOMAIN_PATH_CONTEXT_KEY);
+            if (unixDomainPath != null)
+                return newUnixDomainChannelWithAddress(unixDomainPath);
             return new ChannelWithAddress(SocketChannel.open(), address);
         }
 
@@ -679,21 +689,26 @@ public class ClientConnector extends ContainerLifeCycle
                 @Override
                 public ChannelWithAddress newChannelWithAddress(ClientConnector clientConnector, SocketAddress address, Map<String, Object> context)
                 {
-                    try
-                    {
-                        ProtocolFamily family = Enum.valueOf(StandardProtocolFamily.class, "UNIX");
-                        SocketChannel socketChannel = (SocketChannel)SocketChannel.class.getMethod("open", ProtocolFamily.class).invoke(null, family);
-                        Class<?> addressClass = Class.forName("java.net.UnixDomainSocketAddress");
-                        SocketAddress socketAddress = (SocketAddress)addressClass.getMethod("of", Path.class).invoke(null, path);
-                        return new ChannelWithAddress(socketChannel, socketAddress);
-                    }
-                    catch (Throwable x)
-                    {
-                        String message = "Unix-Domain SocketChannels are available starting from Java 16, your Java version is: " + JavaVersion.VERSION;
-                        throw new UnsupportedOperationException(message, x);
-                    }
+                    return newUnixDomainChannelWithAddress(path);
                 }
             };
         }
+
+        private static ChannelWithAddress newUnixDomainChannelWithAddress(Path path)
+        {
+            try
+            {
+                ProtocolFamily family = Enum.valueOf(StandardProtocolFamily.class, "UNIX");
+                SocketChannel socketChannel = (SocketChannel)SocketChannel.class.getMethod("open", ProtocolFamily.class).invoke(null, family);
+                Class<?> addressClass = Class.forName("java.net.UnixDomainSocketAddress");
+                SocketAddress socketAddress = (SocketAddress)addressClass.getMethod("of", Path.class).invoke(null, path);
+                return new ChannelWithAddress(socketChannel, socketAddress);
+            }
+            catch (Throwable x)
+            {
+                String message = "Unix-Domain SocketChannels are available starting from Java 16, your Java version is: " + JavaVersion.VERSION;
+                throw new UnsupportedOperationException(message, x);
+            }
+        }
     }
 }
@@ -153,6 +153,29 @@ public interface Connection extends Closeable
 
         public void onClosed(Connection connection);
 
+        /**
+         * <p>Callback method invoked when the connection is closed, with the cause of the close.</p>
+         * <p>The default implementation calls {@link #onClosed(Connection)}.</p>
+         *
+         * @param connection the connection that was closed
+         * @param cause the cause of the close, or null if the connection was closed normally
+         */
+        public default void onClosed(Connection connection, Throwable cause)
+        {
+            onClosed(connection);
+        }
+
+        /**
+         * <p>Callback method invoked when the connection failed to read,
+         * for example because of an idle timeout or a network failure.</p>
+         *
+         * @param connection the connection that failed to read
+         * @param failure the read failure, a {@link java.util.concurrent.TimeoutException} in case of idle timeout
+         */
+        public default void onReadFailure(Connection connection, Throwable failure)
+        {
+        }
+
         public static class Adapter implements Listener
         {
             @Override
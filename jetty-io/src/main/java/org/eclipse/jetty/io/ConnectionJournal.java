@@ -0,0 +1,421 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.IOException;
+import java.time.Instant;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.Deque;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.AtomicLong;
+import javax.net.ssl.ExtendedSSLSession;
+import javax.net.ssl.SNIHostName;
+import javax.net.ssl.SNIServerName;
+import javax.net.ssl.SSLEngine;
+import javax.net.ssl.SSLSession;
+
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.io.ssl.SslHandshakeListener;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.component.DumpableCollection;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A {@link Connection.Listener} that records the lifecycle events of connections
+ * in a bounded, per-connection, journal.</p>
+ * <p>Adding an instance of this class as a bean to a ServerConnector or
+ * ConnectionFactory (for the server) or to HttpClient (for the client)
+ * records, for every connection, when it is opened, the TLS handshake details,
+ * the connections that are opened on the same endpoint as a result of protocol
+ * negotiation or upgrade, idle timeouts, read failures and the close cause.</p>
+ * <p>Each connection journal has a unique ID and keeps at most
+ * {@link #getMaxEvents() max events}, discarding the oldest ones.
+ * The journals of the open connections, and of the
+ * {@link #getMaxClosedConnections() most recently closed connections},
+ * are included in the {@link #dump() dump} and can be retrieved via JMX
+ * by connection ID, which helps to diagnose sporadic connection drops.</p>
+ */
+@ManagedObject("Records the lifecycle events of connections")
+public class ConnectionJournal extends AbstractLifeCycle implements Connection.Listener, Dumpable
+{
+    private final AtomicLong _ids = new AtomicLong();
+    private final Map<EndPoint, Journal> _endPoints = new ConcurrentHashMap<>();
+    private final Map<Long, Journal> _open = new ConcurrentHashMap<>();
+    private final AutoLock _lock = new AutoLock();
+    private final Deque<Journal> _closed = new ArrayDeque<>();
+    private int _maxEvents = 32;
+    private int _maxClosedConnections = 64;
+
+    /**
+     * @return the max number of events recorded for each connection
+     */
+    @ManagedAttribute("The max number of events recorded for each connection")
+    public int getMaxEvents()
+    {
+        return _maxEvents;
+    }
+
+    /**
+     * @param maxEvents the max number of events recorded for each connection
+     */
+    public void setMaxEvents(int maxEvents)
+    {
+        if (maxEvents < 1)
+            throw new IllegalArgumentException("Invalid max events " + maxEvents);
+        _maxEvents = maxEvents;
+    }
+
+    /**
+     * @return the max number of journals of closed connections that are retained
+     */
+    @ManagedAttribute("The max number of journals of closed connections that are retained")
+    public int getMaxClosedConnections()
+    {
+        return _maxClosedConnections;
+    }
+
+    /**
+     * @param maxClosedConnections the max number of journals of closed connections that are retained
+     */
+    public void setMaxClosedConnections(int maxClosedConnections)
+    {
+        _maxClosedConnections = maxClosedConnections;
+    }
+
+    @ManagedAttribute(value = "The IDs of the open connections", readonly = true)
+    public List<Long> getOpenConnectionIds()
+    {
+        List<Long> ids = new ArrayList<>(_open.keySet());
+        ids.sort(null);
+        return ids;
+    }
+
+    @ManagedAttribute(value = "The IDs of the retained closed connections", readonly = true)
+    public List<Long> getClosedConnectionIds()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            List<Long> ids = new ArrayList<>();
+            for (Journal journal : _closed)
+            {
+                ids.add(journal._id);
+            }
+            return ids;
+        }
+    }
+
+    /**
+     * @param id the connection ID
+     * @return the events recorded for the given connection, or null if there is no such connection
+     */
+    public List<String> getEvents(long id)
+    {
+        Journal journal = findJournal(id);
+        return journal == null ? null : journal.getEvents();
+    }
+
+    /**
+     * @param id the connection ID
+     * @return the journal of the given connection, one event per line, or null if there is no such connection
+     */
+    @ManagedOperation(value = "The journal of the given connection", impact = "INFO")
+    public String getConnectionJournal(@Name("id") long id)
+    {
+        Journal journal = findJournal(id);
+        if (journal == null)
+            return null;
+        return journal + System.lineSeparator() + String.join(System.lineSeparator(), journal.getEvents());
+    }
+
+    /**
+     * <p>Returns the ID of the connection journal the given endpoint belongs to.</p>
+     *
+     * @param endPoint the endpoint of the connection
+     * @return the connection ID, or -1 if the endpoint is not tracked
+     */
+    public long getConnectionId(EndPoint endPoint)
+    {
+        Journal journal = _endPoints.get(endPoint);
+        return journal == null ? -1 : journal._id;
+    }
+
+    /**
+     * <p>Records an application specific event in the journal of the connection of the given endpoint.</p>
+     *
+     * @param endPoint the endpoint of the connection
+     * @param event the event description
+     */
+    public void record(EndPoint endPoint, String event)
+    {
+        Journal journal = _endPoints.get(endPoint);
+        if (journal != null)
+            journal.record(event);
+    }
+
+    @ManagedOperation(value = "Clears the journals of the closed connections", impact = "ACTION")
+    public void reset()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            _closed.clear();
+        }
+    }
+
+    private Journal findJournal(long id)
+    {
+        Journal journal = _open.get(id);
+        if (journal != null)
+            return journal;
+        try (AutoLock l = _lock.lock())
+        {
+            for (Journal closed : _closed)
+            {
+                if (closed._id == id)
+                    return closed;
+            }
+        }
+        return null;
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        _endPoints.clear();
+        _open.clear();
+        reset();
+    }
+
+    @Override
+    public void onOpened(Connection connection)
+    {
+        if (!isStarted())
+            return;
+        EndPoint endPoint = connection.getEndPoint();
+        Journal journal = _endPoints.get(endPoint);
+        if (journal == null)
+        {
+            journal = new Journal(_ids.incrementAndGet(), endPoint);
+            _endPoints.put(endPoint, journal);
+            _open.put(journal._id, journal);
+            journal.record(String.format("OPENED %s->%s", endPoint.getRemoteSocketAddress(), endPoint.getLocalSocketAddress()));
+        }
+        journal.record("CONNECTION " + connection.getClass().getSimpleName());
+
+        if (connection instanceof SslConnection)
+        {
+            SslConnection sslConnection = (SslConnection)connection;
+            EndPoint decrypted = sslConnection.getDecryptedEndPoint();
+            _endPoints.put(decrypted, journal);
+            journal._nestedEndPoints.add(decrypted);
+            sslConnection.addHandshakeListener(new HandshakeRecorder(journal));
+        }
+    }
+
+    @Override
+    public void onClosed(Connection connection)
+    {
+        onClosed(connection, null);
+    }
+
+    @Override
+    public void onClosed(Connection connection, Throwable cause)
+    {
+        EndPoint endPoint = connection.getEndPoint();
+        Journal journal = _endPoints.get(endPoint);
+        if (journal == null)
+            return;
+        String name = connection.getClass().getSimpleName();
+        if (cause == null)
+            journal.record(String.format("CLOSED %s bytes=%d/%d", name, connection.getBytesIn(), connection.getBytesOut()));
+        else
+            journal.record(String.format("CLOSED %s bytes=%d/%d cause=%s", name, connection.getBytesIn(), connection.getBytesOut(), cause));
+
+        // Only the network endpoint closes the whole journal,
+        // not the upgrades or the closes of the nested endpoints.
+        if (endPoint == journal._endPoint && !endPoint.isOpen())
+        {
+            _endPoints.remove(endPoint);
+            for (EndPoint nested : journal._nestedEndPoints)
+            {
+                _endPoints.remove(nested);
+            }
+            _open.remove(journal._id);
+            journal.close();
+            int max = getMaxClosedConnections();
+            if (max > 0)
+            {
+                try (AutoLock l = _lock.lock())
+                {
+                    _closed.addLast(journal);
+                    while (_closed.size() > max)
+                    {
+                        _closed.removeFirst();
+                    }
+                }
+            }
+        }
+    }
+
+    @Override
+    public void onReadFailure(Connection connection, Throwable failure)
+    {
+        Journal journal = _endPoints.get(connection.getEndPoint());
+        if (journal == null)
+            return;
+        if (failure instanceof TimeoutException)
+            journal.record(String.format("IDLE_TIMEOUT %s idleTimeout=%dms", connection.getClass().getSimpleName(), connection.getEndPoint().getIdleTimeout()));
+        else
+            journal.record(String.format("READ_FAILURE %s %s", connection.getClass().getSimpleName(), failure));
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        List<Journal> closed;
+        try (AutoLock l = _lock.lock())
+        {
+            closed = new ArrayList<>(_closed);
+        }
+        Dumpable.dumpObjects(out, indent, this,
+            new DumpableCollection("open", new ArrayList<>(_open.values())),
+            new DumpableCollection("closed", closed));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[open=%d]", getClass().getSimpleName(), hashCode(), _open.size());
+    }
+
+    private static String toSNI(SSLSession session)
+    {
+        if (!(session instanceof ExtendedSSLSession))
+            return null;
+        List<SNIServerName> names = ((ExtendedSSLSession)session).getRequestedServerNames();
+        for (SNIServerName name : names)
+        {
+            if (name instanceof SNIHostName)
+                return ((SNIHostName)name).getAsciiName();
+        }
+        return null;
+    }
+
+    private class Journal implements Dumpable
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final Deque<String> _events = new ArrayDeque<>();
+        private final List<EndPoint> _nestedEndPoints = new ArrayList<>(1);
+        private final long _id;
+        private final EndPoint _endPoint;
+        private final String _remote;
+        private final long _created = System.currentTimeMillis();
+        private long _dropped;
+        private long _closedTime;
+
+        private Journal(long id, EndPoint endPoint)
+        {
+            _id = id;
+            _endPoint = endPoint;
+            _remote = String.valueOf(endPoint.getRemoteSocketAddress());
+        }
+
+        private void record(String event)
+        {
+            String entry = Instant.now() + " " + event;
+            int max = getMaxEvents();
+            try (AutoLock l = _lock.lock())
+            {
+                _events.addLast(entry);
+                while (_events.size() > max)
+                {
+                    _events.removeFirst();
+                    ++_dropped;
+                }
+            }
+        }
+
+        private void close()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                _closedTime = System.currentTimeMillis();
+            }
+        }
+
+        private List<String> getEvents()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return new ArrayList<>(_events);
+            }
+        }
+
+        @Override
+        public void dump(Appendable out, String indent) throws IOException
+        {
+            Dumpable.dumpObjects(out, indent, this, getEvents().toArray());
+        }
+
+        @Override
+        public String toString()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                long duration = (_closedTime > 0 ? _closedTime : System.currentTimeMillis()) - _created;
+                return String.format("connection#%d[%s,%s,duration=%dms,dropped=%d]",
+                    _id, _remote, _closedTime > 0 ? "closed" : "open", duration, _dropped);
+            }
+        }
+    }
+
+    private static class HandshakeRecorder implements SslHandshakeListener
+    {
+        private final Journal _journal;
+
+        private HandshakeRecorder(Journal journal)
+        {
+            _journal = journal;
+        }
+
+        @Override
+        public void handshakeSucceeded(Event event)
+        {
+            SSLEngine sslEngine = event.getSSLEngine();
+            SSLSession session = sslEngine.getSession();
+            String alpn = sslEngine.getApplicationProtocol();
+            _journal.record(String.format("TLS_HANDSHAKE %s %s sni=%s alpn=%s resumed=%b",
+                session.getProtocol(),
+                session.getCipherSuite(),
+                toSNI(session),
+                StringUtil.isEmpty(alpn) ? null : alpn,
+                session.getCreationTime() < _journal._created));
+        }
+
+        @Override
+        public void handshakeFailed(Event event, Throwable failure)
+        {
+            _journal.record("TLS_HANDSHAKE_FAILED " + failure);
+        }
+    }
+}
@@ -134,6 +134,16 @@ public interface EndPoint extends Closeable
         return getRemoteAddress();
     }
 
+    /**
+     * @return the latest sample of the TCP telemetry of this {@code EndPoint}, or {@code null}
+     * if this {@code EndPoint} is not a TCP socket or has not been sampled
+     * @see SocketTelemetry
+     */
+    default TcpInfo getTcpInfo()
+    {
+        return null;
+    }
+
     /**
      * @return whether this EndPoint is open
      */
@@ -14,6 +14,7 @@
 package org.eclipse.jetty.io;
 
 import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Pool;
 
 /**
  * Extension of the {@link ArrayByteBufferPool} whose bucket sizes increase exponentially instead of linearly.
@@ -81,13 +82,30 @@ public class LogarithmicArrayByteBufferPool extends ArrayByteBufferPool
      */
     public LogarithmicArrayByteBufferPool(int minCapacity, int maxCapacity, int maxQueueLength, long maxHeapMemory, long maxDirectMemory, long retainedHeapMemory, long retainedDirectMemory)
     {
-        super(minCapacity, -1, maxCapacity, maxQueueLength, maxHeapMemory, maxDirectMemory, retainedHeapMemory, retainedDirectMemory);
+        this(minCapacity, maxCapacity, maxQueueLength, maxHeapMemory, maxDirectMemory, retainedHeapMemory, retainedDirectMemory, null);
+    }
+
+    /**
+     * Creates a new ByteBufferPool with the given configuration.
+     *
+     * @param minCapacity the minimum ByteBuffer capacity
+     * @param maxCapacity the maximum ByteBuffer capacity
+     * @param maxQueueLength the maximum ByteBuffer queue length
+     * @param maxHeapMemory the max heap memory in bytes
+     * @param maxDirectMemory the max direct memory in bytes
+     * @param retainedHeapMemory the max heap memory in bytes, -1 for unlimited retained memory or 0 to use default heuristic
+     * @param retainedDirectMemory the max direct memory in bytes, -1 for unlimited retained memory or 0 to use default heuristic
+     * @param retainedStrategyType the strategy used to look up retained ByteBuffers, or null for the default strategy
+     */
+    public LogarithmicArrayByteBufferPool(int minCapacity, int maxCapacity, int maxQueueLength, long maxHeapMemory, long maxDirectMemory, long retainedHeapMemory, long retainedDirectMemory, Pool.StrategyType retainedStrategyType)
+    {
+        super(minCapacity, -1, maxCapacity, maxQueueLength, maxHeapMemory, maxDirectMemory, retainedHeapMemory, retainedDirectMemory, retainedStrategyType);
     }
 
     @Override
     protected RetainableByteBufferPool newRetainableByteBufferPool(int factor, int maxCapacity, int maxBucketSize, long retainedHeapMemory, long retainedDirectMemory)
     {
-        return new LogarithmicRetainablePool(0, maxCapacity, maxBucketSize, retainedHeapMemory, retainedDirectMemory);
+        return new LogarithmicRetainablePool(0, maxCapacity, maxBucketSize, retainedHeapMemory, retainedDirectMemory, getRetainedStrategyType());
     }
 
     @Override
@@ -147,6 +165,11 @@ public class LogarithmicArrayByteBufferPool extends ArrayByteBufferPool
         }
 
         public LogarithmicRetainablePool(int minCapacity, int maxCapacity, int maxBucketSize, long maxHeapMemory, long maxDirectMemory)
+        {
+            this(minCapacity, maxCapacity, maxBucketSize, maxHeapMemory, maxDirectMemory, null);
+        }
+
+        public LogarithmicRetainablePool(int minCapacity, int maxCapacity, int maxBucketSize, long maxHeapMemory, long maxDirectMemory, Pool.StrategyType strategyType)
         {
             super(minCapacity,
                 -1,
@@ -155,7 +178,8 @@ public class LogarithmicArrayByteBufferPool extends ArrayByteBufferPool
                 c -> 32 - Integer.numberOfLeadingZeros(c - 1),
                 i -> 1 << i,
                 maxHeapMemory,
-                maxDirectMemory
+                maxDirectMemory,
+                strategyType
             );
         }
     }
@@ -31,6 +31,8 @@ public class SocketChannelEndPoint extends SelectableChannelEndPoint
 {
     private static final Logger LOG = LoggerFactory.getLogger(SocketChannelEndPoint.class);
 
+    private volatile TcpInfo _tcpInfo;
+
     public SocketChannelEndPoint(SocketChannel channel, ManagedSelector selector, SelectionKey key, Scheduler scheduler)
     {
         super(scheduler, channel, selector, key);
@@ -56,6 +58,20 @@ public class SocketChannelEndPoint extends SelectableChannelEndPoint
         }
     }
 
+    @Override
+    public TcpInfo getTcpInfo()
+    {
+        return _tcpInfo;
+    }
+
+    /**
+     * @param tcpInfo the latest sample of the TCP telemetry of this EndPoint
+     */
+    public void setTcpInfo(TcpInfo tcpInfo)
+    {
+        _tcpInfo = tcpInfo;
+    }
+
     @Override
     protected void doShutdownOutput()
     {
@@ -127,4 +143,11 @@ public class SocketChannelEndPoint extends SelectableChannelEndPoint
 
         return true;
     }
+
+    @Override
+    public String toEndPointString()
+    {
+        TcpInfo tcpInfo = _tcpInfo;
+        return tcpInfo == null ? super.toEndPointString() : super.toEndPointString() + tcpInfo;
+    }
 }
@@ -0,0 +1,264 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.IOException;
+import java.nio.channels.SocketChannel;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.function.ToLongFunction;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.component.DumpableCollection;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Connection.Listener} that periodically samples the TCP telemetry
+ * of the connections, such as the round trip time, the retransmissions and the
+ * congestion window.</p>
+ * <p>Adding an instance of this class as a bean to a ServerConnector (for the server)
+ * or to HttpClient (for the client) tracks the connections over TCP sockets, and samples
+ * them every {@link #getSamplePeriod() sample period} using a {@link TcpInfo.Provider}.
+ * The latest sample of each connection is available via {@link EndPoint#getTcpInfo()},
+ * is included in the endpoint {@code toString()} and so in the connection dumps,
+ * and is aggregated in the attributes of this class.</p>
+ * <p>The available telemetry depends on the platform, see {@link TcpInfo.Provider#load()}.</p>
+ */
+@ManagedObject("Samples the TCP telemetry of connections")
+public class SocketTelemetry extends ContainerLifeCycle implements Connection.Listener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(SocketTelemetry.class);
+
+    private final Set<SocketChannelEndPoint> _endPoints = ConcurrentHashMap.newKeySet();
+    private Scheduler _scheduler;
+    private TcpInfo.Provider _provider;
+    private long _samplePeriod = 5000;
+    private Scheduler.Task _task;
+
+    public SocketTelemetry()
+    {
+        this(null);
+    }
+
+    /**
+     * @param scheduler the scheduler used to sample periodically, or null to use a private scheduler
+     */
+    public SocketTelemetry(@Name("scheduler") Scheduler scheduler)
+    {
+        _scheduler = scheduler;
+        addBean(scheduler);
+    }
+
+    /**
+     * @return the provider of the TCP telemetry
+     */
+    public TcpInfo.Provider getProvider()
+    {
+        return _provider;
+    }
+
+    /**
+     * @param provider the provider of the TCP telemetry, or null to {@link TcpInfo.Provider#load() load} the best one
+     */
+    public void setProvider(TcpInfo.Provider provider)
+    {
+        _provider = provider;
+    }
+
+    @ManagedAttribute("The period between samples in ms, or 0 to only sample on demand")
+    public long getSamplePeriod()
+    {
+        return _samplePeriod;
+    }
+
+    public void setSamplePeriod(long samplePeriod)
+    {
+        _samplePeriod = samplePeriod;
+    }
+
+    @ManagedAttribute("The number of sampled connections")
+    public int getConnections()
+    {
+        return _endPoints.size();
+    }
+
+    @ManagedAttribute("The average smoothed round trip time of the connections in us, or -1 if not available")
+    public long getRttAverage()
+    {
+        return average(TcpInfo::getRtt);
+    }
+
+    @ManagedAttribute("The average retransmission timeout of the connections in us, or -1 if not available")
+    public long getRtoAverage()
+    {
+        return average(TcpInfo::getRto);
+    }
+
+    @ManagedAttribute("The average congestion window of the connections in segments, or -1 if not available")
+    public long getCongestionWindowAverage()
+    {
+        return average(TcpInfo::getCongestionWindow);
+    }
+
+    @ManagedAttribute("The total retransmissions of the connections, or -1 if not available")
+    public long getRetransmits()
+    {
+        long total = TcpInfo.UNKNOWN;
+        for (SocketChannelEndPoint endPoint : _endPoints)
+        {
+            TcpInfo tcpInfo = endPoint.getTcpInfo();
+            if (tcpInfo != null && tcpInfo.getRetransmits() >= 0)
+                total = Math.max(total, 0) + tcpInfo.getRetransmits();
+        }
+        return total;
+    }
+
+    private long average(ToLongFunction<TcpInfo> value)
+    {
+        long total = 0;
+        int count = 0;
+        for (SocketChannelEndPoint endPoint : _endPoints)
+        {
+            TcpInfo tcpInfo = endPoint.getTcpInfo();
+            if (tcpInfo == null)
+                continue;
+            long v = value.applyAsLong(tcpInfo);
+            if (v < 0)
+                continue;
+            total += v;
+            ++count;
+        }
+        return count == 0 ? TcpInfo.UNKNOWN : total / count;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_provider == null)
+            _provider = TcpInfo.Provider.load();
+        if (_scheduler == null)
+        {
+            _scheduler = new ScheduledExecutorScheduler(String.format("SocketTelemetry@%x", hashCode()), true);
+            addBean(_scheduler, true);
+        }
+        super.doStart();
+        schedule();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        Scheduler.Task task = _task;
+        if (task != null)
+            task.cancel();
+        _task = null;
+        _endPoints.clear();
+        super.doStop();
+    }
+
+    private void schedule()
+    {
+        long period = getSamplePeriod();
+        if (isRunning() && period > 0)
+            _task = _scheduler.schedule(this::run, period, TimeUnit.MILLISECONDS);
+    }
+
+    private void run()
+    {
+        try
+        {
+            sample();
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Could not sample TCP telemetry", x);
+        }
+        finally
+        {
+            schedule();
+        }
+    }
+
+    /**
+     * <p>Samples the TCP telemetry of all the tracked connections.</p>
+     */
+    @ManagedOperation(value = "Samples the TCP telemetry of the connections", impact = "ACTION")
+    public void sample()
+    {
+        Map<SocketChannel, SocketChannelEndPoint> channels = new HashMap<>();
+        for (SocketChannelEndPoint endPoint : _endPoints)
+        {
+            if (endPoint.isOpen())
+                channels.put(endPoint.getChannel(), endPoint);
+        }
+        if (channels.isEmpty())
+            return;
+        Map<SocketChannel, TcpInfo> samples = getProvider().sample(channels.keySet());
+        samples.forEach((channel, tcpInfo) ->
+        {
+            SocketChannelEndPoint endPoint = channels.get(channel);
+            if (endPoint != null)
+                endPoint.setTcpInfo(tcpInfo);
+        });
+        if (LOG.isDebugEnabled())
+            LOG.debug("Sampled {}/{} connections with {}", samples.size(), channels.size(), getProvider());
+    }
+
+    @Override
+    public void onOpened(Connection connection)
+    {
+        if (!isStarted())
+            return;
+        EndPoint endPoint = connection.getEndPoint();
+        if (endPoint instanceof SocketChannelEndPoint)
+            _endPoints.add((SocketChannelEndPoint)endPoint);
+    }
+
+    @Override
+    public void onClosed(Connection connection)
+    {
+        EndPoint endPoint = connection.getEndPoint();
+        if (!endPoint.isOpen())
+            _endPoints.remove(endPoint);
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        List<String> connections = new ArrayList<>();
+        for (SocketChannelEndPoint endPoint : _endPoints)
+        {
+            connections.add(endPoint.getRemoteSocketAddress() + " " + endPoint.getTcpInfo());
+        }
+        dumpObjects(out, indent, new DumpableCollection("connections", connections));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[connections=%d,provider=%s]", getClass().getSimpleName(), hashCode(), getConnections(), getProvider());
+    }
+}
@@ -0,0 +1,420 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.BufferedReader;
+import java.io.IOException;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.net.SocketOption;
+import java.net.StandardSocketOptions;
+import java.nio.ByteBuffer;
+import java.nio.ByteOrder;
+import java.nio.channels.SocketChannel;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.util.Collection;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.ServiceLoader;
+
+import org.eclipse.jetty.util.TypeUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A sample of the TCP telemetry of a connection, such as the round trip time,
+ * the retransmissions and the congestion window, to correlate application
+ * latency with network conditions.</p>
+ * <p>The telemetry is obtained by a {@link Provider}, and the values that the
+ * provider could not obtain on the current platform are {@link #UNKNOWN}.</p>
+ *
+ * @see SocketTelemetry
+ */
+public class TcpInfo
+{
+    /**
+     * The value of the telemetry that is not available.
+     */
+    public static final long UNKNOWN = -1;
+
+    private final long timeStamp = System.currentTimeMillis();
+    private final long rtt;
+    private final long rttVariance;
+    private final long rto;
+    private final long retransmits;
+    private final long congestionWindow;
+    private final long sendQueue;
+    private final long receiveQueue;
+    private final long sendBufferSize;
+    private final long receiveBufferSize;
+
+    /**
+     * @param rtt the smoothed round trip time, in microseconds
+     * @param rttVariance the round trip time variance, in microseconds
+     * @param rto the retransmission timeout, in microseconds
+     * @param retransmits the number of retransmissions
+     * @param congestionWindow the congestion window, in segments
+     * @param sendQueue the bytes sent but not yet acknowledged by the peer
+     * @param receiveQueue the bytes received but not yet read by the application
+     * @param sendBufferSize the size of the socket send buffer
+     * @param receiveBufferSize the size of the socket receive buffer
+     */
+    public TcpInfo(long rtt, long rttVariance, long rto, long retransmits, long congestionWindow, long sendQueue, long receiveQueue, long sendBufferSize, long receiveBufferSize)
+    {
+        this.rtt = rtt;
+        this.rttVariance = rttVariance;
+        this.rto = rto;
+        this.retransmits = retransmits;
+        this.congestionWindow = congestionWindow;
+        this.sendQueue = sendQueue;
+        this.receiveQueue = receiveQueue;
+        this.sendBufferSize = sendBufferSize;
+        this.receiveBufferSize = receiveBufferSize;
+    }
+
+    /**
+     * @return the time this sample was taken, in milliseconds since the epoch
+     */
+    public long getTimeStamp()
+    {
+        return timeStamp;
+    }
+
+    /**
+     * @return the smoothed round trip time, in microseconds
+     */
+    public long getRtt()
+    {
+        return rtt;
+    }
+
+    /**
+     * @return the round trip time variance, in microseconds
+     */
+    public long getRttVariance()
+    {
+        return rttVariance;
+    }
+
+    /**
+     * @return the retransmission timeout, in microseconds
+     */
+    public long getRto()
+    {
+        return rto;
+    }
+
+    /**
+     * @return the number of retransmissions
+     */
+    public long getRetransmits()
+    {
+        return retransmits;
+    }
+
+    /**
+     * @return the congestion window, in segments
+     */
+    public long getCongestionWindow()
+    {
+        return congestionWindow;
+    }
+
+    /**
+     * @return the bytes sent but not yet acknowledged by the peer
+     */
+    public long getSendQueue()
+    {
+        return sendQueue;
+    }
+
+    /**
+     * @return the bytes received but not yet read by the application
+     */
+    public long getReceiveQueue()
+    {
+        return receiveQueue;
+    }
+
+    /**
+     * @return the size of the socket send buffer
+     */
+    public long getSendBufferSize()
+    {
+        return sendBufferSize;
+    }
+
+    /**
+     * @return the size of the socket receive buffer
+     */
+    public long getReceiveBufferSize()
+    {
+        return receiveBufferSize;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("tcp{rtt=%d/%dus,rto=%dus,retrans=%d,cwnd=%d,q=%d/%d,buf=%d/%d}",
+            rtt, rttVariance, rto, retransmits, congestionWindow, sendQueue, receiveQueue, sendBufferSize, receiveBufferSize);
+    }
+
+    private static long option(SocketChannel channel, SocketOption<Integer> option)
+    {
+        try
+        {
+            return channel.getOption(option);
+        }
+        catch (Throwable x)
+        {
+            return UNKNOWN;
+        }
+    }
+
+    /**
+     * <p>Obtains the TCP telemetry of socket channels.</p>
+     * <p>Java does not expose the {@code TCP_INFO} socket option, so that the available
+     * telemetry depends on the platform: {@link #load()} returns the first provider
+     * discovered via {@link ServiceLoader} that could be initialized, for example one
+     * based on the foreign function API of recent JDKs, then {@link ProcNetTcpProvider}
+     * on Linux, and finally {@link SocketOptionsProvider}.</p>
+     */
+    public interface Provider
+    {
+        /**
+         * <p>Initializes this Provider.</p>
+         *
+         * @throws RuntimeException if this provider is not available on the current platform
+         */
+        public default void init()
+        {
+        }
+
+        /**
+         * @param channels the connected channels to sample
+         * @return the samples of the given channels, possibly missing the channels that could not be sampled
+         */
+        public Map<SocketChannel, TcpInfo> sample(Collection<SocketChannel> channels);
+
+        /**
+         * @return the best provider available on the current platform
+         */
+        public static Provider load()
+        {
+            Logger logger = LoggerFactory.getLogger(Provider.class);
+            Provider[] result = new Provider[1];
+            TypeUtil.serviceProviderStream(ServiceLoader.load(Provider.class)).forEach(service ->
+            {
+                if (result[0] != null)
+                    return;
+                try
+                {
+                    Provider provider = service.get();
+                    provider.init();
+                    result[0] = provider;
+                }
+                catch (Throwable x)
+                {
+                    if (logger.isDebugEnabled())
+                        logger.debug("Unable to load TcpInfo provider {}", service.type(), x);
+                }
+            });
+            if (result[0] == null)
+            {
+                try
+                {
+                    Provider provider = new ProcNetTcpProvider();
+                    provider.init();
+                    result[0] = provider;
+                }
+                catch (Throwable x)
+                {
+                    if (logger.isDebugEnabled())
+                        logger.debug("Unable to load TcpInfo provider {}", ProcNetTcpProvider.class, x);
+                    result[0] = new SocketOptionsProvider();
+                }
+            }
+            if (logger.isDebugEnabled())
+                logger.debug("TcpInfo provider: {}", result[0]);
+            return result[0];
+        }
+    }
+
+    /**
+     * <p>A {@link Provider} that only reports the socket buffer sizes,
+     * available on all platforms.</p>
+     */
+    public static class SocketOptionsProvider implements Provider
+    {
+        @Override
+        public Map<SocketChannel, TcpInfo> sample(Collection<SocketChannel> channels)
+        {
+            Map<SocketChannel, TcpInfo> result = new HashMap<>();
+            for (SocketChannel channel : channels)
+            {
+                result.put(channel, new TcpInfo(UNKNOWN, UNKNOWN, UNKNOWN, UNKNOWN, UNKNOWN, UNKNOWN, UNKNOWN,
+                    option(channel, StandardSocketOptions.SO_SNDBUF), option(channel, StandardSocketOptions.SO_RCVBUF)));
+            }
+            return result;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+        }
+    }
+
+    /**
+     * <p>A {@link Provider} that reads the Linux {@code /proc/net/tcp} and
+     * {@code /proc/net/tcp6} files, once for all the sampled channels.</p>
+     * <p>These files report the retransmission timeout, the consecutive retransmissions
+     * of the current timeout, the congestion window and the queue sizes, but not the
+     * round trip time, which is {@link #UNKNOWN}.</p>
+     */
+    public static class ProcNetTcpProvider implements Provider
+    {
+        private static final Logger LOG = LoggerFactory.getLogger(ProcNetTcpProvider.class);
+        private static final long MICROS_PER_CLOCK_TICK = 1_000_000 / 100;
+
+        private final Path directory;
+
+        public ProcNetTcpProvider()
+        {
+            this(Paths.get("/proc/net"));
+        }
+
+        /**
+         * @param directory the directory containing the {@code tcp} and {@code tcp6} files
+         */
+        public ProcNetTcpProvider(Path directory)
+        {
+            this.directory = directory;
+        }
+
+        @Override
+        public void init()
+        {
+            if (!Files.isReadable(directory.resolve("tcp")))
+                throw new UnsupportedOperationException("Not readable: " + directory.resolve("tcp"));
+        }
+
+        @Override
+        public Map<SocketChannel, TcpInfo> sample(Collection<SocketChannel> channels)
+        {
+            Map<String, SocketChannel> keys = new HashMap<>();
+            for (SocketChannel channel : channels)
+            {
+                try
+                {
+                    String key = key(channel.getLocalAddress(), channel.getRemoteAddress());
+                    if (key != null)
+                        keys.put(key, channel);
+                }
+                catch (IOException x)
+                {
+                    // The channel is closed.
+                }
+            }
+
+            Map<SocketChannel, TcpInfo> result = new HashMap<>();
+            for (String file : new String[]{"tcp", "tcp6"})
+            {
+                if (result.size() == keys.size())
+                    break;
+                Path path = directory.resolve(file);
+                if (!Files.isReadable(path))
+                    continue;
+                try (BufferedReader reader = Files.newBufferedReader(path, StandardCharsets.US_ASCII))
+                {
+                    // Skip the header line.
+                    String line = reader.readLine();
+                    while ((line = reader.readLine()) != null)
+                    {
+                        parse(line, keys, result);
+                    }
+                }
+                catch (IOException x)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not read {}", path, x);
+                }
+            }
+            return result;
+        }
+
+        private void parse(String line, Map<String, SocketChannel> keys, Map<SocketChannel, TcpInfo> result)
+        {
+            // Format: sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+            // followed by: refcount pointer rto ato quick/pingpong cwnd ssthresh.
+            String[] fields = line.trim().split("\\s+");
+            if (fields.length < 16)
+                return;
+            try
+            {
+                String key = key(address(fields[1]), address(fields[2]));
+                SocketChannel channel = keys.get(key);
+                if (channel == null)
+                    return;
+                String[] queues = fields[4].split(":");
+                result.put(channel, new TcpInfo(UNKNOWN, UNKNOWN,
+                    Long.parseLong(fields[12]) * MICROS_PER_CLOCK_TICK,
+                    Long.parseLong(fields[6], 16),
+                    Long.parseLong(fields[15]),
+                    Long.parseLong(queues[0], 16),
+                    Long.parseLong(queues[1], 16),
+                    option(channel, StandardSocketOptions.SO_SNDBUF),
+                    option(channel, StandardSocketOptions.SO_RCVBUF)));
+            }
+            catch (RuntimeException | IOException x)
+            {
+                // Lines of sockets in other states may not be fully formatted.
+            }
+        }
+
+        private static InetSocketAddress address(String field) throws IOException
+        {
+            // Addresses are printed as 32-bit words in host byte order, the port in hex.
+            int colon = field.indexOf(':');
+            String hex = field.substring(0, colon);
+            ByteBuffer bytes = ByteBuffer.allocate(hex.length() / 2).order(ByteOrder.nativeOrder());
+            for (int i = 0; i < hex.length(); i += 8)
+            {
+                bytes.putInt((int)Long.parseLong(hex.substring(i, i + 8), 16));
+            }
+            // An IPv4-mapped IPv6 address is returned as an Inet4Address.
+            InetAddress address = InetAddress.getByAddress(bytes.array());
+            return new InetSocketAddress(address, Integer.parseInt(field.substring(colon + 1), 16));
+        }
+
+        private static String key(SocketAddress local, SocketAddress remote)
+        {
+            if (!(local instanceof InetSocketAddress) || !(remote instanceof InetSocketAddress))
+                return null;
+            InetSocketAddress l = (InetSocketAddress)local;
+            InetSocketAddress r = (InetSocketAddress)remote;
+            return l.getAddress().getHostAddress() + ":" + l.getPort() + "|" + r.getAddress().getHostAddress() + ":" + r.getPort();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), directory);
+        }
+    }
+}
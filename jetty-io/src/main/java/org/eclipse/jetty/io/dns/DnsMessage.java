@@ -0,0 +1,334 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.net.InetAddress;
+import java.nio.BufferUnderflowException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+/**
+ * <p>Encodes DNS queries and decodes DNS responses, as defined by RFC 1035.</p>
+ */
+public class DnsMessage
+{
+    public static final int NOERROR = 0;
+    public static final int FORMERR = 1;
+    public static final int SERVFAIL = 2;
+    public static final int NXDOMAIN = 3;
+    public static final int REFUSED = 5;
+
+    private static final int CLASS_IN = 1;
+    private static final int TYPE_OPT = 41;
+    private static final int MAX_POINTERS = 64;
+
+    /**
+     * <p>Encodes a recursive query for the given name and record type.</p>
+     *
+     * @param id the query id
+     * @param name the name to query
+     * @param type the record type to query
+     * @param udpPayloadSize the EDNS(0) UDP payload size to advertise, or 0 to not use EDNS(0)
+     * @return the encoded query
+     */
+    public static byte[] newQuery(int id, String name, int type, int udpPayloadSize)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream(32 + name.length());
+        writeShort(output, id);
+        // Recursion desired.
+        writeShort(output, 0x0100);
+        // One question, no answers, no authorities.
+        writeShort(output, 1);
+        writeShort(output, 0);
+        writeShort(output, 0);
+        writeShort(output, udpPayloadSize > 0 ? 1 : 0);
+        for (String label : name.split("\\."))
+        {
+            if (label.isEmpty())
+                continue;
+            byte[] bytes = label.getBytes(StandardCharsets.US_ASCII);
+            if (bytes.length > 63)
+                throw new IllegalArgumentException("Invalid DNS name " + name);
+            output.write(bytes.length);
+            output.write(bytes, 0, bytes.length);
+        }
+        output.write(0);
+        writeShort(output, type);
+        writeShort(output, CLASS_IN);
+        if (udpPayloadSize > 0)
+        {
+            // EDNS(0) OPT pseudo-record, RFC 6891.
+            output.write(0);
+            writeShort(output, TYPE_OPT);
+            writeShort(output, udpPayloadSize);
+            writeShort(output, 0);
+            writeShort(output, 0);
+            writeShort(output, 0);
+        }
+        return output.toByteArray();
+    }
+
+    /**
+     * @param message the encoded DNS message
+     * @return the id of the given DNS message
+     */
+    public static int getId(ByteBuffer message)
+    {
+        return message.getShort(message.position()) & 0xFFFF;
+    }
+
+    /**
+     * <p>Decodes a DNS response.</p>
+     *
+     * @param buffer the encoded response
+     * @return the decoded response
+     * @throws IOException if the response is malformed
+     */
+    public static DnsMessage parse(ByteBuffer buffer) throws IOException
+    {
+        try
+        {
+            ByteBuffer message = buffer.slice();
+            int id = message.getShort() & 0xFFFF;
+            int flags = message.getShort() & 0xFFFF;
+            int questions = message.getShort() & 0xFFFF;
+            int answers = message.getShort() & 0xFFFF;
+            int authorities = message.getShort() & 0xFFFF;
+            // Skip the additional records count.
+            message.getShort();
+
+            String questionName = null;
+            int questionType = 0;
+            for (int i = 0; i < questions; ++i)
+            {
+                String name = readName(message);
+                int type = message.getShort() & 0xFFFF;
+                message.getShort();
+                if (i == 0)
+                {
+                    questionName = name;
+                    questionType = type;
+                }
+            }
+
+            List<DnsRecord> answerRecords = readRecords(message, answers);
+            List<DnsRecord> authorityRecords = readRecords(message, authorities);
+            return new DnsMessage(id, flags, questionName, questionType, answerRecords, authorityRecords);
+        }
+        catch (BufferUnderflowException | IndexOutOfBoundsException x)
+        {
+            throw new IOException("Malformed DNS message", x);
+        }
+    }
+
+    private static List<DnsRecord> readRecords(ByteBuffer message, int count) throws IOException
+    {
+        List<DnsRecord> records = new ArrayList<>(count);
+        for (int i = 0; i < count; ++i)
+        {
+            String name = readName(message);
+            int type = message.getShort() & 0xFFFF;
+            int recordClass = message.getShort() & 0xFFFF;
+            long ttl = message.getInt() & 0xFFFFFFFFL;
+            int length = message.getShort() & 0xFFFF;
+            int end = message.position() + length;
+            if (end > message.limit())
+                throw new IOException("Malformed DNS record " + name);
+            if (recordClass == CLASS_IN)
+            {
+                switch (type)
+                {
+                    case DnsRecord.A:
+                    case DnsRecord.AAAA:
+                    {
+                        if (length != (type == DnsRecord.A ? 4 : 16))
+                            throw new IOException("Malformed DNS address record " + name);
+                        byte[] bytes = new byte[length];
+                        message.get(bytes);
+                        records.add(DnsRecord.address(name, type, ttl, InetAddress.getByAddress(name, bytes)));
+                        break;
+                    }
+                    case DnsRecord.CNAME:
+                        records.add(DnsRecord.cname(name, ttl, readName(message)));
+                        break;
+                    case DnsRecord.SRV:
+                    {
+                        int priority = message.getShort() & 0xFFFF;
+                        int weight = message.getShort() & 0xFFFF;
+                        int port = message.getShort() & 0xFFFF;
+                        records.add(DnsRecord.srv(name, ttl, priority, weight, port, readName(message)));
+                        break;
+                    }
+                    case DnsRecord.SVCB:
+                    case DnsRecord.HTTPS:
+                    {
+                        int priority = message.getShort() & 0xFFFF;
+                        String target = readName(message);
+                        // RFC 9460, section 2.2.
+                        Map<Integer, byte[]> params = new LinkedHashMap<>();
+                        while (message.position() < end)
+                        {
+                            int key = message.getShort() & 0xFFFF;
+                            byte[] value = new byte[message.getShort() & 0xFFFF];
+                            if (message.position() + value.length > end)
+                                throw new IOException("Malformed DNS service binding record " + name);
+                            message.get(value);
+                            params.put(key, value);
+                        }
+                        records.add(DnsRecord.svcb(name, type, ttl, priority, target, Collections.unmodifiableMap(params)));
+                        break;
+                    }
+                    case DnsRecord.SOA:
+                    {
+                        readName(message);
+                        readName(message);
+                        // Skip serial, refresh, retry and expire.
+                        message.position(message.position() + 16);
+                        records.add(DnsRecord.soa(name, ttl, message.getInt() & 0xFFFFFFFFL));
+                        break;
+                    }
+                    default:
+                        records.add(DnsRecord.other(name, type, ttl));
+                        break;
+                }
+            }
+            message.position(end);
+        }
+        return records;
+    }
+
+    private static String readName(ByteBuffer message) throws IOException
+    {
+        StringBuilder name = new StringBuilder();
+        int position = message.position();
+        int resume = -1;
+        int pointers = 0;
+        while (true)
+        {
+            int length = message.get(position) & 0xFF;
+            if (length == 0)
+            {
+                ++position;
+                break;
+            }
+            if ((length & 0xC0) == 0xC0)
+            {
+                // Compression pointer.
+                if (++pointers > MAX_POINTERS)
+                    throw new IOException("Malformed DNS name");
+                if (resume < 0)
+                    resume = position + 2;
+                position = ((length & 0x3F) << 8) | (message.get(position + 1) & 0xFF);
+                continue;
+            }
+            if ((length & 0xC0) != 0)
+                throw new IOException("Malformed DNS name");
+            if (name.length() > 0)
+                name.append('.');
+            for (int i = 1; i <= length; ++i)
+            {
+                name.append((char)(message.get(position + i) & 0xFF));
+            }
+            position += length + 1;
+        }
+        message.position(resume < 0 ? position : resume);
+        return name.toString();
+    }
+
+    private static void writeShort(ByteArrayOutputStream output, int value)
+    {
+        output.write((value >>> 8) & 0xFF);
+        output.write(value & 0xFF);
+    }
+
+    private final int id;
+    private final int flags;
+    private final String questionName;
+    private final int questionType;
+    private final List<DnsRecord> answers;
+    private final List<DnsRecord> authorities;
+
+    private DnsMessage(int id, int flags, String questionName, int questionType, List<DnsRecord> answers, List<DnsRecord> authorities)
+    {
+        this.id = id;
+        this.flags = flags;
+        this.questionName = questionName;
+        this.questionType = questionType;
+        this.answers = answers;
+        this.authorities = authorities;
+    }
+
+    public int getId()
+    {
+        return id;
+    }
+
+    /**
+     * @return whether this message is a response
+     */
+    public boolean isResponse()
+    {
+        return (flags & 0x8000) != 0;
+    }
+
+    /**
+     * @return whether this response was truncated and must be retried over TCP
+     */
+    public boolean isTruncated()
+    {
+        return (flags & 0x0200) != 0;
+    }
+
+    /**
+     * @return the response code, such as {@link #NOERROR} or {@link #NXDOMAIN}
+     */
+    public int getResponseCode()
+    {
+        return flags & 0x000F;
+    }
+
+    public String getQuestionName()
+    {
+        return questionName;
+    }
+
+    public int getQuestionType()
+    {
+        return questionType;
+    }
+
+    public List<DnsRecord> getAnswers()
+    {
+        return answers;
+    }
+
+    public List<DnsRecord> getAuthorities()
+    {
+        return authorities;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[id=%d,rcode=%d,tc=%b,question=%s/%d,answers=%s]",
+            getClass().getSimpleName(), hashCode(), id, getResponseCode(), isTruncated(), questionName, questionType, answers);
+    }
+}
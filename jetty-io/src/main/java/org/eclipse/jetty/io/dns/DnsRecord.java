@@ -0,0 +1,223 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.net.InetAddress;
+import java.util.Map;
+
+/**
+ * <p>A DNS resource record, as defined by RFC 1035.</p>
+ * <p>Only the record types used for address resolution and service
+ * discovery are decoded: {@link #A}, {@link #AAAA}, {@link #CNAME},
+ * {@link #SRV}, {@link #SOA}, {@link #SVCB} and {@link #HTTPS}.</p>
+ */
+public class DnsRecord
+{
+    public static final int A = 1;
+    public static final int CNAME = 5;
+    public static final int SOA = 6;
+    public static final int AAAA = 28;
+    public static final int SRV = 33;
+    public static final int SVCB = 64;
+    public static final int HTTPS = 65;
+
+    /**
+     * The {@code alpn} service parameter key of {@link #SVCB} and {@link #HTTPS} records, RFC 9460.
+     */
+    public static final int SVC_PARAM_ALPN = 1;
+    /**
+     * The {@code port} service parameter key of {@link #SVCB} and {@link #HTTPS} records, RFC 9460.
+     */
+    public static final int SVC_PARAM_PORT = 3;
+    /**
+     * The {@code ech} service parameter key of {@link #SVCB} and {@link #HTTPS} records,
+     * whose value is an {@code ECHConfigList}.
+     */
+    public static final int SVC_PARAM_ECH = 5;
+
+    private final String name;
+    private final int type;
+    private final long ttl;
+    private final InetAddress address;
+    private final String target;
+    private final int priority;
+    private final int weight;
+    private final int port;
+    private final long minimum;
+    private final Map<Integer, byte[]> svcParams;
+
+    private DnsRecord(String name, int type, long ttl, InetAddress address, String target, int priority, int weight, int port, long minimum)
+    {
+        this(name, type, ttl, address, target, priority, weight, port, minimum, Map.of());
+    }
+
+    private DnsRecord(String name, int type, long ttl, InetAddress address, String target, int priority, int weight, int port, long minimum, Map<Integer, byte[]> svcParams)
+    {
+        this.name = name;
+        this.type = type;
+        this.ttl = ttl;
+        this.address = address;
+        this.target = target;
+        this.priority = priority;
+        this.weight = weight;
+        this.port = port;
+        this.minimum = minimum;
+        this.svcParams = svcParams;
+    }
+
+    static DnsRecord address(String name, int type, long ttl, InetAddress address)
+    {
+        return new DnsRecord(name, type, ttl, address, null, 0, 0, 0, 0);
+    }
+
+    static DnsRecord cname(String name, long ttl, String target)
+    {
+        return new DnsRecord(name, CNAME, ttl, null, target, 0, 0, 0, 0);
+    }
+
+    static DnsRecord srv(String name, long ttl, int priority, int weight, int port, String target)
+    {
+        return new DnsRecord(name, SRV, ttl, null, target, priority, weight, port, 0);
+    }
+
+    static DnsRecord soa(String name, long ttl, long minimum)
+    {
+        return new DnsRecord(name, SOA, ttl, null, null, 0, 0, 0, minimum);
+    }
+
+    static DnsRecord svcb(String name, int type, long ttl, int priority, String target, Map<Integer, byte[]> svcParams)
+    {
+        return new DnsRecord(name, type, ttl, null, target, priority, 0, 0, 0, svcParams);
+    }
+
+    static DnsRecord other(String name, int type, long ttl)
+    {
+        return new DnsRecord(name, type, ttl, null, null, 0, 0, 0, 0);
+    }
+
+    /**
+     * @return the owner name of this record, without the trailing dot
+     */
+    public String getName()
+    {
+        return name;
+    }
+
+    /**
+     * @return the record type
+     */
+    public int getType()
+    {
+        return type;
+    }
+
+    /**
+     * @return the time to live of this record, in seconds
+     */
+    public long getTtl()
+    {
+        return ttl;
+    }
+
+    /**
+     * @return the address of {@link #A} and {@link #AAAA} records, or null
+     */
+    public InetAddress getAddress()
+    {
+        return address;
+    }
+
+    /**
+     * @return the canonical name of {@link #CNAME} records, the target host of {@link #SRV},
+     * {@link #SVCB} and {@link #HTTPS} records, or null
+     */
+    public String getTarget()
+    {
+        return target;
+    }
+
+    /**
+     * @return the priority of {@link #SRV} records, the {@code SvcPriority} of {@link #SVCB}
+     * and {@link #HTTPS} records, where {@code 0} indicates an alias record
+     */
+    public int getPriority()
+    {
+        return priority;
+    }
+
+    /**
+     * @return the weight of {@link #SRV} records
+     */
+    public int getWeight()
+    {
+        return weight;
+    }
+
+    /**
+     * @return the port of {@link #SRV} records
+     */
+    public int getPort()
+    {
+        return port;
+    }
+
+    /**
+     * @return the negative caching time to live of {@link #SOA} records, in seconds
+     */
+    public long getMinimum()
+    {
+        return minimum;
+    }
+
+    /**
+     * @param key the service parameter key, such as {@link #SVC_PARAM_ECH}
+     * @return the raw value of the given service parameter of {@link #SVCB}
+     * and {@link #HTTPS} records, or null if the parameter is not present
+     */
+    public byte[] getSvcParam(int key)
+    {
+        byte[] value = svcParams.get(key);
+        return value == null ? null : value.clone();
+    }
+
+    /**
+     * @return the {@code ECHConfigList} of {@link #SVCB} and {@link #HTTPS} records, or null
+     */
+    public byte[] getECHConfigList()
+    {
+        return getSvcParam(SVC_PARAM_ECH);
+    }
+
+    @Override
+    public String toString()
+    {
+        switch (type)
+        {
+            case A:
+            case AAAA:
+                return String.format("%s %d %s %s", name, ttl, type == A ? "A" : "AAAA", address.getHostAddress());
+            case CNAME:
+                return String.format("%s %d CNAME %s", name, ttl, target);
+            case SRV:
+                return String.format("%s %d SRV %d %d %d %s", name, ttl, priority, weight, port, target);
+            case SOA:
+                return String.format("%s %d SOA minimum=%d", name, ttl, minimum);
+            case SVCB:
+            case HTTPS:
+                return String.format("%s %d %s %d %s params=%s", name, ttl, type == SVCB ? "SVCB" : "HTTPS", priority, target, svcParams.keySet());
+            default:
+                return String.format("%s %d TYPE%d", name, ttl, type);
+        }
+    }
+}
@@ -0,0 +1,835 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.IOException;
+import java.net.Inet6Address;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.URI;
+import java.net.UnknownHostException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.Comparator;
+import java.util.Iterator;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.regex.Pattern;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link SocketAddressResolver} that performs DNS resolution asynchronously,
+ * without blocking any thread, and that caches the results.</p>
+ * <p>Unlike {@link SocketAddressResolver.Async}, that delegates to the blocking
+ * {@link InetAddress#getAllByName(String)} in a pooled thread, this resolver sends
+ * DNS queries over UDP to the configured {@link #setNameServers(List) name servers}
+ * (by default those listed in {@code /etc/resolv.conf}), retrying over TCP when a
+ * response is truncated, and optionally falling back to
+ * {@link #setDnsOverHttpsURI(URI) DNS over HTTPS} when no name server replies.</p>
+ * <p>{@code A} and {@code AAAA} queries are sent in parallel, {@code CNAME} chains are
+ * followed, and the entries of the {@code /etc/hosts} file take precedence over DNS.
+ * Positive results are cached for their TTL, clamped between {@link #getMinTtl()} and
+ * {@link #getMaxTtl()}; negative results are cached for the TTL indicated by the
+ * {@code SOA} record of the response, as specified by RFC 2308, or for
+ * {@link #getNegativeTtl()}.</p>
+ * <p>{@link #resolveSrv(String, Promise)} allows to resolve {@code SRV} records, and
+ * {@link #resolveHttps(String, int, Promise)} allows to resolve {@code HTTPS} records.</p>
+ * <p>Typical usage with {@code HttpClient}:</p>
+ * <pre>
+ * HttpClient httpClient = new HttpClient();
+ * DnsResolver resolver = new DnsResolver();
+ * httpClient.addBean(resolver);
+ * httpClient.setSocketAddressResolver(resolver);
+ * httpClient.start();
+ * </pre>
+ * <p>Search domains and the {@code ndots} option of {@code /etc/resolv.conf}
+ * are not supported: host names are always resolved as absolute names.</p>
+ */
+@ManagedObject("Asynchronous DNS resolver")
+public class DnsResolver extends ContainerLifeCycle implements SocketAddressResolver
+{
+    private static final Logger LOG = LoggerFactory.getLogger(DnsResolver.class);
+    private static final Pattern IPV4 = Pattern.compile("\\d{1,3}(\\.\\d{1,3}){3}");
+    private static final int DNS_PORT = 53;
+    private static final int UDP_PAYLOAD_SIZE = 1232;
+    private static final int MAX_CNAME_DEPTH = 8;
+
+    private final Map<String, CacheEntry> cache = new ConcurrentHashMap<>();
+    private final Map<String, List<InetAddress>> hosts = new ConcurrentHashMap<>();
+    private final LongAdder cacheHits = new LongAdder();
+    private final LongAdder cacheMisses = new LongAdder();
+    private final LongAdder queries = new LongAdder();
+    private final LongAdder queryFailures = new LongAdder();
+    private final Scheduler scheduler;
+    private final List<InetSocketAddress> nameServers = new ArrayList<>();
+    private volatile List<NameServer> servers = List.of();
+    private DohDnsTransport dohTransport;
+    private URI dohURI;
+    private Path resolvConf = Paths.get("/etc/resolv.conf");
+    private Path hostsFile = Paths.get("/etc/hosts");
+    private long queryTimeout = 2000;
+    private int attempts = 2;
+    private long minTtl;
+    private long maxTtl = TimeUnit.DAYS.toSeconds(1);
+    private long negativeTtl = 30;
+    private int maxCacheSize = 4096;
+    private boolean preferIPv6;
+
+    public DnsResolver()
+    {
+        this(null);
+    }
+
+    /**
+     * @param scheduler the scheduler for query timeouts, or {@code null} to use a private one
+     */
+    public DnsResolver(Scheduler scheduler)
+    {
+        if (scheduler == null)
+        {
+            this.scheduler = new ScheduledExecutorScheduler("DnsResolver-Scheduler", true);
+            addBean(this.scheduler, true);
+        }
+        else
+        {
+            this.scheduler = scheduler;
+            addBean(scheduler, false);
+        }
+    }
+
+    public Scheduler getScheduler()
+    {
+        return scheduler;
+    }
+
+    /**
+     * @return the configured name servers, or those read from {@link #getResolvConf()} when started
+     */
+    public List<InetSocketAddress> getNameServers()
+    {
+        if (isStarted())
+            return servers.stream().map(NameServer::getAddress).collect(Collectors.toList());
+        return List.copyOf(nameServers);
+    }
+
+    /**
+     * @param nameServers the name servers to query, in order; if empty,
+     * the name servers are read from {@link #getResolvConf()}
+     */
+    public void setNameServers(List<InetSocketAddress> nameServers)
+    {
+        if (isStarted())
+            throw new IllegalStateException(getState());
+        this.nameServers.clear();
+        this.nameServers.addAll(nameServers);
+    }
+
+    public Path getResolvConf()
+    {
+        return resolvConf;
+    }
+
+    public void setResolvConf(Path resolvConf)
+    {
+        this.resolvConf = resolvConf;
+    }
+
+    public Path getHostsFile()
+    {
+        return hostsFile;
+    }
+
+    /**
+     * @param hostsFile the hosts file, or {@code null} to not use a hosts file
+     */
+    public void setHostsFile(Path hostsFile)
+    {
+        this.hostsFile = hostsFile;
+    }
+
+    @ManagedAttribute("The DNS over HTTPS URI used when no name server replies")
+    public URI getDnsOverHttpsURI()
+    {
+        return dohURI;
+    }
+
+    /**
+     * @param uri the DNS over HTTPS URI (for example {@code https://dns.example/dns-query}),
+     * used when no name server replies, or {@code null} to disable DNS over HTTPS
+     */
+    public void setDnsOverHttpsURI(URI uri)
+    {
+        if (isStarted())
+            throw new IllegalStateException(getState());
+        this.dohURI = uri;
+    }
+
+    @ManagedAttribute("The timeout in milliseconds of each DNS query")
+    public long getQueryTimeout()
+    {
+        return queryTimeout;
+    }
+
+    public void setQueryTimeout(long queryTimeout)
+    {
+        this.queryTimeout = queryTimeout;
+    }
+
+    @ManagedAttribute("The number of attempts for each name server")
+    public int getAttempts()
+    {
+        return attempts;
+    }
+
+    public void setAttempts(int attempts)
+    {
+        this.attempts = Math.max(1, attempts);
+    }
+
+    @ManagedAttribute("The min time in seconds that positive results are cached")
+    public long getMinTtl()
+    {
+        return minTtl;
+    }
+
+    public void setMinTtl(long minTtl)
+    {
+        this.minTtl = minTtl;
+    }
+
+    @ManagedAttribute("The max time in seconds that positive results are cached")
+    public long getMaxTtl()
+    {
+        return maxTtl;
+    }
+
+    public void setMaxTtl(long maxTtl)
+    {
+        this.maxTtl = maxTtl;
+    }
+
+    @ManagedAttribute("The time in seconds that negative results are cached when the response has no SOA record")
+    public long getNegativeTtl()
+    {
+        return negativeTtl;
+    }
+
+    public void setNegativeTtl(long negativeTtl)
+    {
+        this.negativeTtl = negativeTtl;
+    }
+
+    @ManagedAttribute("The max number of cached results")
+    public int getMaxCacheSize()
+    {
+        return maxCacheSize;
+    }
+
+    public void setMaxCacheSize(int maxCacheSize)
+    {
+        this.maxCacheSize = maxCacheSize;
+    }
+
+    @ManagedAttribute("Whether IPv6 addresses are returned before IPv4 addresses")
+    public boolean isPreferIPv6()
+    {
+        return preferIPv6;
+    }
+
+    public void setPreferIPv6(boolean preferIPv6)
+    {
+        this.preferIPv6 = preferIPv6;
+    }
+
+    @ManagedAttribute("The number of cached results")
+    public int getCacheSize()
+    {
+        return cache.size();
+    }
+
+    @ManagedAttribute("The number of lookups served by the cache")
+    public long getCacheHits()
+    {
+        return cacheHits.sum();
+    }
+
+    @ManagedAttribute("The number of lookups not served by the cache")
+    public long getCacheMisses()
+    {
+        return cacheMisses.sum();
+    }
+
+    @ManagedAttribute("The number of DNS queries sent")
+    public long getQueries()
+    {
+        return queries.sum();
+    }
+
+    @ManagedAttribute("The number of DNS queries that failed or timed out")
+    public long getQueryFailures()
+    {
+        return queryFailures.sum();
+    }
+
+    @ManagedOperation(value = "Clears the cache", impact = "ACTION")
+    public void clearCache()
+    {
+        cache.clear();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        cacheHits.reset();
+        cacheMisses.reset();
+        queries.reset();
+        queryFailures.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        List<InetSocketAddress> addresses = new ArrayList<>(nameServers);
+        if (addresses.isEmpty())
+            addresses.addAll(readResolvConf());
+        if (addresses.isEmpty() && dohURI == null)
+            throw new IllegalStateException("No DNS name servers");
+
+        List<NameServer> servers = new ArrayList<>();
+        for (InetSocketAddress address : addresses)
+        {
+            NameServer server = new NameServer(address);
+            addBean(server.udp, true);
+            servers.add(server);
+        }
+        this.servers = servers;
+        if (dohURI != null)
+        {
+            dohTransport = new DohDnsTransport(dohURI);
+            addBean(dohTransport, true);
+        }
+
+        readHostsFile();
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        servers.forEach(server -> removeBean(server.udp));
+        servers = List.of();
+        if (dohTransport != null)
+            removeBean(dohTransport);
+        dohTransport = null;
+        hosts.clear();
+        cache.clear();
+    }
+
+    private List<InetSocketAddress> readResolvConf() throws IOException
+    {
+        List<InetSocketAddress> result = new ArrayList<>();
+        if (resolvConf == null || !Files.isReadable(resolvConf))
+            return result;
+        for (String line : Files.readAllLines(resolvConf, StandardCharsets.UTF_8))
+        {
+            String[] tokens = tokenize(line);
+            if (tokens.length >= 2 && "nameserver".equals(tokens[0]))
+            {
+                InetAddress address = toInetAddress(tokens[1]);
+                if (address != null)
+                    result.add(new InetSocketAddress(address, DNS_PORT));
+            }
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("Read name servers {} from {}", result, resolvConf);
+        return result;
+    }
+
+    private void readHostsFile() throws IOException
+    {
+        if (hostsFile == null || !Files.isReadable(hostsFile))
+            return;
+        for (String line : Files.readAllLines(hostsFile, StandardCharsets.UTF_8))
+        {
+            String[] tokens = tokenize(line);
+            if (tokens.length < 2)
+                continue;
+            InetAddress address = toInetAddress(tokens[0]);
+            if (address == null)
+                continue;
+            for (int i = 1; i < tokens.length; ++i)
+            {
+                hosts.computeIfAbsent(tokens[i].toLowerCase(Locale.ENGLISH), k -> new ArrayList<>()).add(address);
+            }
+        }
+    }
+
+    private static String[] tokenize(String line)
+    {
+        int hash = line.indexOf('#');
+        if (hash >= 0)
+            line = line.substring(0, hash);
+        line = line.trim();
+        return line.isEmpty() ? new String[0] : line.split("\\s+");
+    }
+
+    /**
+     * @param host the host
+     * @return the address if the host is an IP literal, {@code null} otherwise
+     */
+    private static InetAddress toInetAddress(String host)
+    {
+        if (host.startsWith("[") && host.endsWith("]"))
+            host = host.substring(1, host.length() - 1);
+        if (!IPV4.matcher(host).matches() && host.indexOf(':') < 0)
+            return null;
+        try
+        {
+            // Does not perform DNS resolution for IP literals.
+            return InetAddress.getByName(host);
+        }
+        catch (UnknownHostException x)
+        {
+            return null;
+        }
+    }
+
+    @Override
+    public void resolve(String host, int port, Promise<List<InetSocketAddress>> promise)
+    {
+        InetAddress literal = toInetAddress(host);
+        if (literal != null)
+        {
+            promise.succeeded(List.of(new InetSocketAddress(literal, port)));
+            return;
+        }
+
+        String name = normalize(host);
+        List<InetAddress> fromHosts = hosts.get(name);
+        if (fromHosts != null)
+        {
+            promise.succeeded(toSocketAddresses(sort(fromHosts), port));
+            return;
+        }
+
+        AddressLookup lookup = new AddressLookup(host, port, promise);
+        lookup(name, DnsRecord.A, 0, Promise.from(lookup::succeeded, lookup::failed));
+        lookup(name, DnsRecord.AAAA, 0, Promise.from(lookup::succeeded, lookup::failed));
+    }
+
+    /**
+     * <p>Resolves the {@code SRV} records with the given name, for example
+     * {@code _sip._tcp.example.com}.</p>
+     * <p>The records are returned sorted by ascending priority and,
+     * for the same priority, by descending weight.</p>
+     *
+     * @param name the name of the {@code SRV} records
+     * @param promise the promise completed with the {@code SRV} records
+     */
+    public void resolveSrv(String name, Promise<List<DnsRecord>> promise)
+    {
+        lookup(normalize(name), DnsRecord.SRV, 0, Promise.from(records ->
+        {
+            List<DnsRecord> result = new ArrayList<>(records);
+            result.sort(Comparator.comparingInt(DnsRecord::getPriority)
+                .thenComparing(Comparator.comparingInt(DnsRecord::getWeight).reversed()));
+            promise.succeeded(result);
+        }, promise::failed));
+    }
+
+    /**
+     * <p>Resolves the {@code HTTPS} records of the given host and port, as defined by RFC 9460,
+     * for example to obtain the {@code ECHConfigList} needed by TLS Encrypted Client Hello.</p>
+     * <p>For ports other than 443, the records are looked up with the {@code _port._https}
+     * prefix, as specified by RFC 9460, section 9.1.</p>
+     * <p>The records are returned sorted by ascending priority, with the alias records
+     * (with priority {@code 0}) first; the promise is failed if no records exist.</p>
+     *
+     * @param host the host name
+     * @param port the port
+     * @param promise the promise completed with the {@code HTTPS} records
+     */
+    public void resolveHttps(String host, int port, Promise<List<DnsRecord>> promise)
+    {
+        String name = port == 443 || port <= 0 ? host : "_" + port + "._https." + host;
+        lookup(normalize(name), DnsRecord.HTTPS, 0, Promise.from(records ->
+        {
+            List<DnsRecord> result = new ArrayList<>(records);
+            result.sort(Comparator.comparingInt(DnsRecord::getPriority));
+            promise.succeeded(result);
+        }, promise::failed));
+    }
+
+    private static String normalize(String name)
+    {
+        name = name.toLowerCase(Locale.ENGLISH);
+        return name.endsWith(".") ? name.substring(0, name.length() - 1) : name;
+    }
+
+    private List<InetAddress> sort(List<InetAddress> addresses)
+    {
+        List<InetAddress> result = new ArrayList<>(addresses);
+        result.sort(Comparator.comparingInt(address -> (address instanceof Inet6Address) == preferIPv6 ? 0 : 1));
+        return result;
+    }
+
+    private static List<InetSocketAddress> toSocketAddresses(List<InetAddress> addresses, int port)
+    {
+        List<InetSocketAddress> result = new ArrayList<>(addresses.size());
+        for (InetAddress address : addresses)
+        {
+            result.add(new InetSocketAddress(address, port));
+        }
+        return result;
+    }
+
+    private void lookup(String name, int type, int depth, Promise<List<DnsRecord>> promise)
+    {
+        String key = type + ":" + name;
+        CacheEntry entry = cache.get(key);
+        if (entry != null)
+        {
+            if (!entry.isExpired(NanoTime.now()))
+            {
+                cacheHits.increment();
+                if (entry.records == null)
+                    promise.failed(new UnknownHostException(name));
+                else
+                    promise.succeeded(entry.records);
+                return;
+            }
+            cache.remove(key, entry);
+        }
+        cacheMisses.increment();
+        new Query(name, type, Promise.from(message -> onResponse(name, type, depth, key, message, promise), promise::failed)).send();
+    }
+
+    private void onResponse(String name, int type, int depth, String key, DnsMessage message, Promise<List<DnsRecord>> promise)
+    {
+        if (message.getResponseCode() == DnsMessage.NXDOMAIN)
+        {
+            cacheNegative(key, message);
+            promise.failed(new UnknownHostException(name));
+            return;
+        }
+
+        // Follow the CNAME chain within the answers.
+        String current = name;
+        long ttl = Long.MAX_VALUE;
+        List<DnsRecord> records = new ArrayList<>();
+        for (int i = 0; i <= MAX_CNAME_DEPTH; ++i)
+        {
+            String target = null;
+            for (DnsRecord record : message.getAnswers())
+            {
+                if (!current.equalsIgnoreCase(record.getName()))
+                    continue;
+                if (record.getType() == type)
+                {
+                    records.add(record);
+                    ttl = Math.min(ttl, record.getTtl());
+                }
+                else if (record.getType() == DnsRecord.CNAME)
+                {
+                    target = normalize(record.getTarget());
+                    ttl = Math.min(ttl, record.getTtl());
+                }
+            }
+            if (!records.isEmpty() || target == null)
+                break;
+            current = target;
+        }
+
+        if (!records.isEmpty())
+        {
+            List<DnsRecord> result = Collections.unmodifiableList(records);
+            cachePositive(key, ttl, result);
+            promise.succeeded(result);
+        }
+        else if (!current.equals(name))
+        {
+            // The answers end with a CNAME without the records of the target.
+            if (depth < MAX_CNAME_DEPTH)
+                lookup(current, type, depth + 1, promise);
+            else
+                promise.failed(new UnknownHostException("CNAME chain too long for " + name));
+        }
+        else
+        {
+            // NODATA response.
+            cacheNegative(key, message);
+            promise.failed(new UnknownHostException(name));
+        }
+    }
+
+    private void cachePositive(String key, long ttl, List<DnsRecord> records)
+    {
+        cache(key, Math.max(minTtl, Math.min(maxTtl, ttl)), records);
+    }
+
+    private void cacheNegative(String key, DnsMessage message)
+    {
+        long ttl = negativeTtl;
+        for (DnsRecord record : message.getAuthorities())
+        {
+            if (record.getType() == DnsRecord.SOA)
+            {
+                // RFC 2308 section 5.
+                ttl = Math.min(record.getTtl(), record.getMinimum());
+                break;
+            }
+        }
+        cache(key, Math.min(maxTtl, ttl), null);
+    }
+
+    private void cache(String key, long ttl, List<DnsRecord> records)
+    {
+        if (ttl <= 0 || maxCacheSize <= 0)
+            return;
+        if (cache.size() >= maxCacheSize)
+        {
+            long now = NanoTime.now();
+            cache.values().removeIf(entry -> entry.isExpired(now));
+            Iterator<String> iterator = cache.keySet().iterator();
+            while (cache.size() >= maxCacheSize && iterator.hasNext())
+            {
+                iterator.next();
+                iterator.remove();
+            }
+        }
+        cache.put(key, new CacheEntry(records, NanoTime.now() + TimeUnit.SECONDS.toNanos(ttl)));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[servers=%s,doh=%s,cache=%d]", getClass().getSimpleName(), hashCode(), getNameServers(), dohURI, cache.size());
+    }
+
+    private static class CacheEntry
+    {
+        private final List<DnsRecord> records;
+        private final long expireNanoTime;
+
+        private CacheEntry(List<DnsRecord> records, long expireNanoTime)
+        {
+            this.records = records;
+            this.expireNanoTime = expireNanoTime;
+        }
+
+        private boolean isExpired(long now)
+        {
+            return NanoTime.isBeforeOrSame(expireNanoTime, now);
+        }
+    }
+
+    private class NameServer
+    {
+        private final UdpDnsTransport udp;
+        private final TcpDnsTransport tcp;
+
+        private NameServer(InetSocketAddress address)
+        {
+            this.udp = new UdpDnsTransport(address, scheduler);
+            this.tcp = new TcpDnsTransport(address, scheduler);
+        }
+
+        private InetSocketAddress getAddress()
+        {
+            return udp.getNameServer();
+        }
+    }
+
+    /**
+     * <p>A query sent to each name server in turn, for the configured number of
+     * attempts, until a name server replies, then to DNS over HTTPS, if configured.</p>
+     */
+    private class Query
+    {
+        private final String name;
+        private final int type;
+        private final Promise<DnsMessage> promise;
+        private final byte[] request;
+        private final List<NameServer> servers = DnsResolver.this.servers;
+        private int tries;
+        private Throwable failure;
+
+        private Query(String name, int type, Promise<DnsMessage> promise)
+        {
+            this.name = name;
+            this.type = type;
+            this.promise = promise;
+            this.request = DnsMessage.newQuery(0, name, type, UDP_PAYLOAD_SIZE);
+        }
+
+        private void send()
+        {
+            if (tries < servers.size() * attempts)
+            {
+                NameServer server = servers.get(tries++ % servers.size());
+                queries.increment();
+                server.udp.send(request, queryTimeout, Promise.from(response -> onUdpResponse(server, response), this::fail));
+            }
+            else if (dohTransport != null && tries == servers.size() * attempts)
+            {
+                ++tries;
+                queries.increment();
+                dohTransport.send(request, queryTimeout, Promise.from(this::onResponse, this::fail));
+            }
+            else
+            {
+                UnknownHostException x = new UnknownHostException("DNS resolution failed for " + name);
+                if (failure != null)
+                    x.initCause(failure);
+                promise.failed(x);
+            }
+        }
+
+        private void onUdpResponse(NameServer server, ByteBuffer response)
+        {
+            DnsMessage message = parse(response);
+            if (message == null)
+                return;
+            if (message.isTruncated())
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Truncated response for {} type {}, retrying over TCP to {}", name, type, server.getAddress());
+                queries.increment();
+                server.tcp.send(request, queryTimeout, Promise.from(this::onResponse, this::fail));
+                return;
+            }
+            complete(message);
+        }
+
+        private void onResponse(ByteBuffer response)
+        {
+            DnsMessage message = parse(response);
+            if (message != null)
+                complete(message);
+        }
+
+        private void complete(DnsMessage message)
+        {
+            int code = message.getResponseCode();
+            if (code == DnsMessage.NOERROR || code == DnsMessage.NXDOMAIN)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Received {}", message);
+                promise.succeeded(message);
+            }
+            else
+            {
+                fail(new IOException("DNS error response code " + code + " for " + name));
+            }
+        }
+
+        private DnsMessage parse(ByteBuffer response)
+        {
+            try
+            {
+                DnsMessage message = DnsMessage.parse(response);
+                if (!message.isResponse() || message.getQuestionType() != type || !name.equalsIgnoreCase(message.getQuestionName()))
+                    throw new IOException("Unexpected DNS response " + message);
+                return message;
+            }
+            catch (IOException x)
+            {
+                fail(x);
+                return null;
+            }
+        }
+
+        private void fail(Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("DNS query failed for {} type {}", name, type, x);
+            queryFailures.increment();
+            failure = x;
+            send();
+        }
+    }
+
+    /**
+     * <p>Aggregates the results of the parallel {@code A} and {@code AAAA} lookups.</p>
+     */
+    private class AddressLookup
+    {
+        private final AtomicInteger pending = new AtomicInteger(2);
+        private final List<InetAddress> addresses = Collections.synchronizedList(new ArrayList<>());
+        private final String host;
+        private final int port;
+        private final Promise<List<InetSocketAddress>> promise;
+        private volatile Throwable failure;
+
+        private AddressLookup(String host, int port, Promise<List<InetSocketAddress>> promise)
+        {
+            this.host = host;
+            this.port = port;
+            this.promise = promise;
+        }
+
+        private void succeeded(List<DnsRecord> records)
+        {
+            for (DnsRecord record : records)
+            {
+                addresses.add(record.getAddress());
+            }
+            complete();
+        }
+
+        private void failed(Throwable x)
+        {
+            failure = x;
+            complete();
+        }
+
+        private void complete()
+        {
+            if (pending.decrementAndGet() > 0)
+                return;
+            List<InetAddress> result;
+            synchronized (addresses)
+            {
+                result = sort(addresses);
+            }
+            if (result.isEmpty())
+                promise.failed(failure != null ? failure : new UnknownHostException(host));
+            else
+                promise.succeeded(toSocketAddresses(result, port));
+        }
+    }
+}
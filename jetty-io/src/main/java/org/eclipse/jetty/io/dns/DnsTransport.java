@@ -0,0 +1,41 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.util.Promise;
+
+/**
+ * <p>A transport of DNS messages to a name server.</p>
+ *
+ * @see UdpDnsTransport
+ * @see TcpDnsTransport
+ * @see DohDnsTransport
+ */
+public interface DnsTransport
+{
+    /**
+     * <p>Sends the given encoded query, and asynchronously returns the encoded
+     * response through the given promise.</p>
+     * <p>Transports may rewrite the query id, for example to avoid collisions
+     * of concurrent queries, so the id of the response may differ from the
+     * id of the given query.</p>
+     *
+     * @param query the encoded query
+     * @param timeout the timeout in milliseconds to receive the response
+     * @param promise the promise completed with the encoded response
+     */
+    void send(byte[] query, long timeout, Promise<ByteBuffer> promise);
+}
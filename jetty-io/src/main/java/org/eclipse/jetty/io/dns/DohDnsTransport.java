@@ -0,0 +1,112 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.IOException;
+import java.net.URI;
+import java.net.http.HttpClient;
+import java.net.http.HttpRequest;
+import java.net.http.HttpResponse;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.concurrent.Executor;
+
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+
+/**
+ * <p>A {@link DnsTransport} using DNS over HTTPS, as specified by RFC 8484.</p>
+ * <p>Queries are sent with the {@code POST} method and the
+ * {@code application/dns-message} media type, using the JDK HTTP client
+ * (the {@code java.net.http} module must be available at runtime), so that
+ * this transport does not depend on the Jetty HTTP client.</p>
+ */
+public class DohDnsTransport extends AbstractLifeCycle implements DnsTransport
+{
+    private static final String MEDIA_TYPE = "application/dns-message";
+
+    private final URI uri;
+    private final Executor executor;
+    private HttpClient client;
+
+    public DohDnsTransport(URI uri)
+    {
+        this(uri, null);
+    }
+
+    public DohDnsTransport(URI uri, Executor executor)
+    {
+        this.uri = uri;
+        this.executor = executor;
+    }
+
+    public URI getURI()
+    {
+        return uri;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        HttpClient.Builder builder = HttpClient.newBuilder();
+        if (executor != null)
+            builder.executor(executor);
+        client = builder.build();
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        client = null;
+    }
+
+    @Override
+    public void send(byte[] query, long timeout, Promise<ByteBuffer> promise)
+    {
+        HttpClient client = this.client;
+        if (client == null)
+        {
+            promise.failed(new IllegalStateException("Not started " + this));
+            return;
+        }
+
+        // RFC 8484 section 4.1: the id should be 0 to maximize HTTP caching.
+        byte[] bytes = query.clone();
+        bytes[0] = 0;
+        bytes[1] = 0;
+        HttpRequest request = HttpRequest.newBuilder(uri)
+            .timeout(Duration.ofMillis(timeout))
+            .header("Accept", MEDIA_TYPE)
+            .header("Content-Type", MEDIA_TYPE)
+            .POST(HttpRequest.BodyPublishers.ofByteArray(bytes))
+            .build();
+        client.sendAsync(request, HttpResponse.BodyHandlers.ofByteArray()).whenComplete((response, failure) ->
+        {
+            if (failure != null)
+                promise.failed(failure);
+            else if (response.statusCode() != 200)
+                promise.failed(new IOException("DNS over HTTPS request to " + uri + " failed with status " + response.statusCode()));
+            else
+                promise.succeeded(ByteBuffer.wrap(response.body()));
+        });
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), uri);
+    }
+}
@@ -0,0 +1,181 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.EOFException;
+import java.net.InetSocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.channels.AsynchronousSocketChannel;
+import java.nio.channels.CompletionHandler;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.AtomicBoolean;
+
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.thread.Scheduler;
+
+/**
+ * <p>A {@link DnsTransport} over TCP.</p>
+ * <p>Each query opens a new connection to the name server, and messages are
+ * framed with a 2 bytes length prefix, as specified by RFC 1035 section 4.2.2.
+ * This transport is typically used to retry queries whose UDP response was
+ * truncated.</p>
+ */
+public class TcpDnsTransport implements DnsTransport
+{
+    private final InetSocketAddress nameServer;
+    private final Scheduler scheduler;
+
+    public TcpDnsTransport(InetSocketAddress nameServer, Scheduler scheduler)
+    {
+        this.nameServer = nameServer;
+        this.scheduler = scheduler;
+    }
+
+    public InetSocketAddress getNameServer()
+    {
+        return nameServer;
+    }
+
+    @Override
+    public void send(byte[] query, long timeout, Promise<ByteBuffer> promise)
+    {
+        AsynchronousSocketChannel channel;
+        try
+        {
+            channel = AsynchronousSocketChannel.open();
+        }
+        catch (Throwable x)
+        {
+            promise.failed(x);
+            return;
+        }
+        new Exchange(channel, query, timeout, promise).connect();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), nameServer);
+    }
+
+    private class Exchange
+    {
+        private final AtomicBoolean complete = new AtomicBoolean();
+        private final AsynchronousSocketChannel channel;
+        private final ByteBuffer request;
+        private final long timeout;
+        private final Promise<ByteBuffer> promise;
+        private final Scheduler.Task task;
+
+        private Exchange(AsynchronousSocketChannel channel, byte[] query, long timeout, Promise<ByteBuffer> promise)
+        {
+            this.channel = channel;
+            this.request = ByteBuffer.allocate(2 + query.length);
+            this.request.putShort((short)query.length).put(query).flip();
+            this.timeout = timeout;
+            this.promise = promise;
+            this.task = scheduler.schedule(this::expire, timeout, TimeUnit.MILLISECONDS);
+        }
+
+        private void connect()
+        {
+            channel.connect(nameServer, null, new Handler<Void>()
+            {
+                @Override
+                public void completed(Void result, Void attachment)
+                {
+                    write();
+                }
+            });
+        }
+
+        private void write()
+        {
+            channel.write(request, null, new Handler<Integer>()
+            {
+                @Override
+                public void completed(Integer result, Void attachment)
+                {
+                    if (request.hasRemaining())
+                        write();
+                    else
+                        read(ByteBuffer.allocate(2), true);
+                }
+            });
+        }
+
+        private void read(ByteBuffer buffer, boolean length)
+        {
+            channel.read(buffer, null, new Handler<Integer>()
+            {
+                @Override
+                public void completed(Integer result, Void attachment)
+                {
+                    if (result < 0)
+                    {
+                        failed(new EOFException("Unexpected close of DNS connection to " + nameServer), null);
+                    }
+                    else if (buffer.hasRemaining())
+                    {
+                        read(buffer, length);
+                    }
+                    else
+                    {
+                        buffer.flip();
+                        if (length)
+                            read(ByteBuffer.allocate(buffer.getShort() & 0xFFFF), false);
+                        else
+                            succeed(buffer);
+                    }
+                }
+            });
+        }
+
+        private void succeed(ByteBuffer response)
+        {
+            if (complete.compareAndSet(false, true))
+            {
+                task.cancel();
+                IO.close(channel);
+                promise.succeeded(response);
+            }
+        }
+
+        private void fail(Throwable failure)
+        {
+            if (complete.compareAndSet(false, true))
+            {
+                task.cancel();
+                IO.close(channel);
+                promise.failed(failure);
+            }
+        }
+
+        private void expire()
+        {
+            fail(new TimeoutException("DNS query timeout after " + timeout + " ms to " + nameServer));
+        }
+
+        private abstract class Handler<T> implements CompletionHandler<T, Void>
+        {
+            @Override
+            public void failed(Throwable failure, Void attachment)
+            {
+                fail(failure);
+            }
+        }
+    }
+}
@@ -0,0 +1,195 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.nio.channels.DatagramChannel;
+import java.security.SecureRandom;
+import java.util.Map;
+import java.util.Random;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link DnsTransport} over UDP.</p>
+ * <p>All the queries to the name server share a single datagram channel:
+ * queries are sent by the caller thread, while responses are received by a
+ * single thread and dispatched to the pending queries by their id, that is
+ * randomized to make spoofing of responses harder.</p>
+ */
+public class UdpDnsTransport extends AbstractLifeCycle implements DnsTransport
+{
+    private static final Logger LOG = LoggerFactory.getLogger(UdpDnsTransport.class);
+    private static final int MAX_RESPONSE_SIZE = 65535;
+
+    private final Map<Integer, Pending> pending = new ConcurrentHashMap<>();
+    private final Random random = new SecureRandom();
+    private final InetSocketAddress nameServer;
+    private final Scheduler scheduler;
+    private DatagramChannel channel;
+    private Thread receiver;
+
+    public UdpDnsTransport(InetSocketAddress nameServer, Scheduler scheduler)
+    {
+        this.nameServer = nameServer;
+        this.scheduler = scheduler;
+    }
+
+    public InetSocketAddress getNameServer()
+    {
+        return nameServer;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        channel = DatagramChannel.open();
+        channel.connect(nameServer);
+        receiver = new Thread(this::receive, "DNS-UDP-" + nameServer);
+        receiver.setDaemon(true);
+        receiver.start();
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        channel.close();
+        receiver.join();
+        ClosedChannelException failure = new ClosedChannelException();
+        pending.values().forEach(p -> p.failed(failure));
+        pending.clear();
+    }
+
+    @Override
+    public void send(byte[] query, long timeout, Promise<ByteBuffer> promise)
+    {
+        if (!isRunning())
+        {
+            promise.failed(new ClosedChannelException());
+            return;
+        }
+
+        Pending item = new Pending(promise);
+        int id;
+        while (true)
+        {
+            id = random.nextInt(0x10000);
+            if (pending.putIfAbsent(id, item) == null)
+                break;
+        }
+        int queryId = id;
+        item.task = scheduler.schedule(() ->
+        {
+            if (pending.remove(queryId, item))
+                item.failed(new TimeoutException("DNS query timeout after " + timeout + " ms to " + nameServer));
+        }, timeout, TimeUnit.MILLISECONDS);
+
+        byte[] bytes = query.clone();
+        bytes[0] = (byte)(id >>> 8);
+        bytes[1] = (byte)id;
+        try
+        {
+            channel.write(ByteBuffer.wrap(bytes));
+        }
+        catch (Throwable x)
+        {
+            if (pending.remove(queryId, item))
+                item.failed(x);
+        }
+    }
+
+    private void receive()
+    {
+        ByteBuffer buffer = ByteBuffer.allocate(MAX_RESPONSE_SIZE);
+        while (channel.isOpen())
+        {
+            try
+            {
+                buffer.clear();
+                channel.read(buffer);
+                buffer.flip();
+                if (buffer.remaining() < 12)
+                    continue;
+                Pending item = pending.remove(DnsMessage.getId(buffer));
+                if (item == null)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Discarding unexpected DNS response from {}", nameServer);
+                    continue;
+                }
+                ByteBuffer response = ByteBuffer.allocate(buffer.remaining());
+                response.put(buffer).flip();
+                item.succeeded(response);
+            }
+            catch (ClosedChannelException x)
+            {
+                return;
+            }
+            catch (IOException x)
+            {
+                // For example ICMP port unreachable on connected channels.
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Unable to receive DNS response from {}", nameServer, x);
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,pending=%d]", getClass().getSimpleName(), hashCode(), nameServer, pending.size());
+    }
+
+    private static class Pending
+    {
+        private final Promise<ByteBuffer> promise;
+        private volatile Scheduler.Task task;
+
+        private Pending(Promise<ByteBuffer> promise)
+        {
+            this.promise = promise;
+        }
+
+        private void succeeded(ByteBuffer response)
+        {
+            cancel();
+            promise.succeeded(response);
+        }
+
+        private void failed(Throwable failure)
+        {
+            cancel();
+            promise.failed(failure);
+        }
+
+        private void cancel()
+        {
+            Scheduler.Task task = this.task;
+            if (task != null)
+                task.cancel();
+        }
+    }
+}
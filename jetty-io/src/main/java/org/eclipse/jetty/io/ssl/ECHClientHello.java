@@ -0,0 +1,283 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.BufferUnderflowException;
+import java.nio.ByteBuffer;
+import java.security.GeneralSecurityException;
+import java.util.Arrays;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+import org.eclipse.jetty.util.ssl.ECHConfig;
+import org.eclipse.jetty.util.ssl.ECHKey;
+import org.eclipse.jetty.util.ssl.HPKE;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>The server-side decryption of a TLS Encrypted Client Hello.</p>
+ * <p>A client that supports ECH sends an outer ClientHello, whose SNI is the public name
+ * of the server ECH configuration, with an {@code encrypted_client_hello} extension that
+ * carries the inner ClientHello, encrypted with {@link HPKE} using the public key of the
+ * ECH configuration.</p>
+ * <p>{@link #decrypt(ByteBuffer, List)} finds the {@link ECHKey} for the configuration
+ * chosen by the client, decrypts the inner ClientHello and reconstructs it by expanding
+ * the {@code ech_outer_extensions} references to the outer ClientHello extensions.
+ * When the decryption is not possible, for example because the client sent a GREASE
+ * extension or used a configuration unknown to the server, the TLS handshake must
+ * continue with the outer ClientHello.</p>
+ * <p>This class only deals with the ClientHello: the TLS provider must complete the
+ * handshake with the inner ClientHello and signal the ECH acceptance in the ServerHello,
+ * see {@link ECHProcessor}.</p>
+ */
+public class ECHClientHello
+{
+    public static final int ENCRYPTED_CLIENT_HELLO = 0xFE0D;
+    public static final int ECH_OUTER_EXTENSIONS = 0xFD00;
+
+    private static final Logger LOG = LoggerFactory.getLogger(ECHClientHello.class);
+    private static final int CLIENT_HELLO_TYPE = 0x01;
+    private static final int OUTER = 0;
+    private static final int INNER = 1;
+
+    private final ECHKey key;
+    private final byte[] innerClientHello;
+
+    private ECHClientHello(ECHKey key, byte[] innerClientHello)
+    {
+        this.key = key;
+        this.innerClientHello = innerClientHello;
+    }
+
+    /**
+     * <p>Decrypts the inner ClientHello carried by the given outer ClientHello.</p>
+     *
+     * @param clientHello the outer ClientHello handshake message, including the 4 bytes
+     * handshake header; the buffer position is not modified
+     * @param keys the server ECH keys
+     * @return the decrypted ClientHello, or null if the outer ClientHello has no
+     * {@code encrypted_client_hello} extension, or if it cannot be decrypted with the given keys
+     * @throws IllegalArgumentException if the bytes are not a valid ClientHello message,
+     * or if the inner ClientHello is not valid
+     */
+    public static ECHClientHello decrypt(ByteBuffer clientHello, List<ECHKey> keys)
+    {
+        try
+        {
+            ByteBuffer message = clientHello.slice();
+            if ((message.get() & 0xFF) != CLIENT_HELLO_TYPE)
+                throw new IllegalArgumentException("Not a ClientHello message");
+            int length = ((message.get() & 0xFF) << 16) | ((message.get() & 0xFF) << 8) | (message.get() & 0xFF);
+            byte[] body = toArray(slice(message, length));
+            Hello outer = Hello.parse(ByteBuffer.wrap(body));
+
+            ByteBuffer extension = outer.extensions.get(ENCRYPTED_CLIENT_HELLO);
+            if (extension == null)
+                return null;
+            extension = extension.slice();
+            int type = extension.get() & 0xFF;
+            if (type != OUTER)
+                throw new IllegalArgumentException("Invalid ECH type in the outer ClientHello " + type);
+            ECHConfig.CipherSuite cipherSuite = new ECHConfig.CipherSuite(extension.getShort() & 0xFFFF, extension.getShort() & 0xFFFF);
+            int configId = extension.get() & 0xFF;
+            byte[] enc = toArray(slice(extension, extension.getShort() & 0xFFFF));
+            int payloadLength = extension.getShort() & 0xFFFF;
+            // The extension is a slice of the body array.
+            int payloadOffset = extension.arrayOffset() + extension.position();
+            byte[] payload = toArray(slice(extension, payloadLength));
+            // The AAD is the outer ClientHello with the payload replaced by zeros.
+            byte[] aad = body.clone();
+            Arrays.fill(aad, payloadOffset, payloadOffset + payloadLength, (byte)0);
+
+            for (ECHKey key : keys)
+            {
+                ECHConfig config = key.getConfig();
+                if (config.getConfigId() != configId || !config.getCipherSuites().contains(cipherSuite))
+                    continue;
+                byte[] encoded;
+                try
+                {
+                    encoded = key.newRecipientContext(cipherSuite, enc).open(aad, payload);
+                }
+                catch (GeneralSecurityException x)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not decrypt ECH with {}", key, x);
+                    continue;
+                }
+                return new ECHClientHello(key, decode(encoded, outer));
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("No ECH key for config id {} and cipher suite {}", configId, cipherSuite);
+            return null;
+        }
+        catch (BufferUnderflowException | IndexOutOfBoundsException x)
+        {
+            throw new IllegalArgumentException("Invalid ClientHello", x);
+        }
+    }
+
+    private static byte[] decode(byte[] encoded, Hello outer)
+    {
+        ByteBuffer buffer = ByteBuffer.wrap(encoded);
+        Hello inner = Hello.parse(buffer);
+        // The padding after the EncodedClientHelloInner must be zeros.
+        while (buffer.hasRemaining())
+        {
+            if (buffer.get() != 0)
+                throw new IllegalArgumentException("Invalid EncodedClientHelloInner padding");
+        }
+        if (inner.sessionId.remaining() != 0)
+            throw new IllegalArgumentException("Invalid EncodedClientHelloInner legacy_session_id");
+        ByteBuffer ech = inner.extensions.get(ENCRYPTED_CLIENT_HELLO);
+        if (ech == null || !ech.hasRemaining() || (ech.get(ech.position()) & 0xFF) != INNER)
+            throw new IllegalArgumentException("Missing inner ECH extension");
+
+        ByteArrayOutputStream extensions = new ByteArrayOutputStream();
+        for (Map.Entry<Integer, ByteBuffer> entry : inner.extensions.entrySet())
+        {
+            if (entry.getKey() == ECH_OUTER_EXTENSIONS)
+            {
+                ByteBuffer references = entry.getValue().slice();
+                references = slice(references, references.get() & 0xFF);
+                while (references.hasRemaining())
+                {
+                    int type = references.getShort() & 0xFFFF;
+                    ByteBuffer referenced = outer.extensions.get(type);
+                    if (type == ENCRYPTED_CLIENT_HELLO || referenced == null)
+                        throw new IllegalArgumentException("Invalid ech_outer_extensions reference " + type);
+                    writeExtension(extensions, type, referenced);
+                }
+            }
+            else
+            {
+                writeExtension(extensions, entry.getKey(), entry.getValue());
+            }
+        }
+
+        ByteArrayOutputStream body = new ByteArrayOutputStream();
+        writeShort(body, inner.version);
+        body.writeBytes(inner.random);
+        // The legacy_session_id is copied from the outer ClientHello.
+        body.write(outer.sessionId.remaining());
+        body.writeBytes(toArray(outer.sessionId.slice()));
+        writeShort(body, inner.cipherSuites.remaining());
+        body.writeBytes(toArray(inner.cipherSuites.slice()));
+        body.write(inner.compressionMethods.remaining());
+        body.writeBytes(toArray(inner.compressionMethods.slice()));
+        writeShort(body, extensions.size());
+        body.writeBytes(extensions.toByteArray());
+
+        ByteArrayOutputStream message = new ByteArrayOutputStream(body.size() + 4);
+        message.write(CLIENT_HELLO_TYPE);
+        message.write(body.size() >>> 16);
+        writeShort(message, body.size());
+        message.writeBytes(body.toByteArray());
+        return message.toByteArray();
+    }
+
+    private static void writeExtension(ByteArrayOutputStream output, int type, ByteBuffer data)
+    {
+        writeShort(output, type);
+        writeShort(output, data.remaining());
+        output.writeBytes(toArray(data.slice()));
+    }
+
+    private static void writeShort(ByteArrayOutputStream output, int value)
+    {
+        output.write((value >>> 8) & 0xFF);
+        output.write(value & 0xFF);
+    }
+
+    private static ByteBuffer slice(ByteBuffer buffer, int length)
+    {
+        if (buffer.remaining() < length)
+            throw new BufferUnderflowException();
+        ByteBuffer slice = buffer.slice();
+        slice.limit(length);
+        buffer.position(buffer.position() + length);
+        return slice;
+    }
+
+    private static byte[] toArray(ByteBuffer buffer)
+    {
+        byte[] bytes = new byte[buffer.remaining()];
+        buffer.get(bytes);
+        return bytes;
+    }
+
+    /**
+     * @return the ECH key that decrypted the inner ClientHello
+     */
+    public ECHKey getKey()
+    {
+        return key;
+    }
+
+    /**
+     * @return the inner ClientHello handshake message, including the 4 bytes handshake header
+     */
+    public byte[] getInnerClientHello()
+    {
+        return innerClientHello.clone();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,%d bytes}", getClass().getSimpleName(), hashCode(), key, innerClientHello.length);
+    }
+
+    private static class Hello
+    {
+        private final int version;
+        private final byte[] random;
+        private final ByteBuffer sessionId;
+        private final ByteBuffer cipherSuites;
+        private final ByteBuffer compressionMethods;
+        private final Map<Integer, ByteBuffer> extensions;
+
+        private Hello(int version, byte[] random, ByteBuffer sessionId, ByteBuffer cipherSuites, ByteBuffer compressionMethods, Map<Integer, ByteBuffer> extensions)
+        {
+            this.version = version;
+            this.random = random;
+            this.sessionId = sessionId;
+            this.cipherSuites = cipherSuites;
+            this.compressionMethods = compressionMethods;
+            this.extensions = extensions;
+        }
+
+        private static Hello parse(ByteBuffer hello)
+        {
+            int version = hello.getShort() & 0xFFFF;
+            byte[] random = toArray(slice(hello, 32));
+            ByteBuffer sessionId = slice(hello, hello.get() & 0xFF);
+            ByteBuffer cipherSuites = slice(hello, hello.getShort() & 0xFFFF);
+            ByteBuffer compressionMethods = slice(hello, hello.get() & 0xFF);
+            Map<Integer, ByteBuffer> extensions = new LinkedHashMap<>();
+            ByteBuffer data = slice(hello, hello.getShort() & 0xFFFF);
+            while (data.hasRemaining())
+            {
+                int type = data.getShort() & 0xFFFF;
+                ByteBuffer extension = slice(data, data.getShort() & 0xFFFF);
+                if (extensions.put(type, extension) != null)
+                    throw new IllegalArgumentException("Duplicate ClientHello extension " + type);
+            }
+            return new Hello(version, random, sessionId, cipherSuites, compressionMethods, extensions);
+        }
+    }
+}
@@ -0,0 +1,124 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.ServiceLoader;
+import javax.net.ssl.SSLEngine;
+
+import org.eclipse.jetty.util.TypeUtil;
+import org.eclipse.jetty.util.ssl.ECHKey;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Configures TLS Encrypted Client Hello on the {@link SSLEngine}s of a TLS provider.</p>
+ * <p>ECH is implemented by the TLS provider, so that implementations of this interface
+ * are specific to a TLS provider and are discovered via {@link ServiceLoader}, similarly
+ * to {@link ALPNProcessor}.</p>
+ */
+public interface ECHProcessor
+{
+    /**
+     * <p>Initializes this ECHProcessor.</p>
+     *
+     * @throws RuntimeException if this processor is unavailable, for example because
+     * the TLS provider does not support ECH
+     */
+    public default void init()
+    {
+    }
+
+    /**
+     * @param sslEngine the SSLEngine to check
+     * @return true if the processor can be applied to the given SSLEngine
+     */
+    public default boolean appliesTo(SSLEngine sslEngine)
+    {
+        return false;
+    }
+
+    /**
+     * <p>Loads and initializes the available processors of the given type.</p>
+     *
+     * @param type the processor type, either {@link Server} or {@link Client}
+     * @param <T> the processor type
+     * @return the processors that could be initialized
+     */
+    public static <T extends ECHProcessor> List<T> load(Class<T> type)
+    {
+        Logger logger = LoggerFactory.getLogger(ECHProcessor.class);
+        List<T> processors = new ArrayList<>();
+        TypeUtil.serviceProviderStream(ServiceLoader.load(type)).forEach(provider ->
+        {
+            try
+            {
+                T processor = provider.get();
+                processor.init();
+                processors.add(processor);
+            }
+            catch (Throwable x)
+            {
+                if (logger.isDebugEnabled())
+                    logger.debug("Unable to load ECH processor {}", provider.type(), x);
+            }
+        });
+        if (logger.isDebugEnabled())
+            logger.debug("ECH processors: {}", processors);
+        return processors;
+    }
+
+    /**
+     * Server-side interface used by ServiceLoader.
+     */
+    public interface Server extends ECHProcessor
+    {
+        /**
+         * <p>Configures the given SSLEngine to decrypt the Encrypted Client Hello
+         * with the given keys, see {@link ECHClientHello}.</p>
+         *
+         * @param sslEngine the SSLEngine to configure
+         * @param keys the server ECH keys
+         * @throws RuntimeException if this processor cannot be configured
+         */
+        public void configure(SSLEngine sslEngine, List<ECHKey> keys);
+    }
+
+    /**
+     * Client-side interface used by ServiceLoader.
+     */
+    public interface Client extends ECHProcessor
+    {
+        /**
+         * <p>Configures the given SSLEngine to send an Encrypted Client Hello.</p>
+         *
+         * @param sslEngine the SSLEngine to configure
+         * @param echConfigList the {@code ECHConfigList} of the server, typically
+         * obtained from its DNS {@code HTTPS} records, or null if not available
+         * @param grease whether to send a GREASE ECH extension when {@code echConfigList} is null
+         * @throws RuntimeException if this processor cannot be configured
+         */
+        public void configure(SSLEngine sslEngine, byte[] echConfigList, boolean grease);
+
+        /**
+         * @param sslEngine the SSLEngine that completed the TLS handshake
+         * @return whether the server accepted the Encrypted Client Hello
+         */
+        public default boolean isAccepted(SSLEngine sslEngine)
+        {
+            return false;
+        }
+    }
+}
@@ -0,0 +1,168 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.security.SecureRandom;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Periodically rotated secret keys, shared via a {@link ResumptionStore}.</p>
+ * <p>Time is divided in epochs of {@link #getRotationPeriod() rotation period}
+ * length; the first node that needs the key of an epoch generates it randomly
+ * and {@link ResumptionStore#putIfAbsent(String, byte[], long) stores it}, and
+ * the other nodes read it from the store, so that all the nodes sharing the
+ * store use the same key at the same time.
+ * The key of the previous epoch remains available via {@link #getKeys()},
+ * so that secrets issued just before a rotation can still be verified.</p>
+ * <p>If the store is not available, a key local to this node is used,
+ * so that resumption still works for clients coming back to this node.</p>
+ */
+public class ResumptionKeys
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResumptionKeys.class);
+
+    private final Map<Long, byte[]> keys = new ConcurrentHashMap<>();
+    private final SecureRandom random = new SecureRandom();
+    private final ResumptionStore store;
+    private final String name;
+    private final int keyLength;
+    private final long rotationPeriod;
+
+    /**
+     * @param store the store shared by the nodes of the cluster
+     * @param name the name of the keys, used as prefix of the store keys
+     * @param keyLength the length of the keys, in bytes
+     * @param rotationPeriod the rotation period of the keys, in milliseconds
+     */
+    public ResumptionKeys(ResumptionStore store, String name, int keyLength, long rotationPeriod)
+    {
+        if (keyLength <= 0)
+            throw new IllegalArgumentException("Invalid key length " + keyLength);
+        if (rotationPeriod <= 0)
+            throw new IllegalArgumentException("Invalid rotation period " + rotationPeriod);
+        this.store = Objects.requireNonNull(store);
+        this.name = Objects.requireNonNull(name);
+        this.keyLength = keyLength;
+        this.rotationPeriod = rotationPeriod;
+    }
+
+    public ResumptionStore getResumptionStore()
+    {
+        return store;
+    }
+
+    public String getName()
+    {
+        return name;
+    }
+
+    public int getKeyLength()
+    {
+        return keyLength;
+    }
+
+    /**
+     * @return the rotation period of the keys, in milliseconds
+     */
+    public long getRotationPeriod()
+    {
+        return rotationPeriod;
+    }
+
+    /**
+     * @return the key of the current epoch
+     */
+    public byte[] getCurrentKey()
+    {
+        return getKey(currentEpoch());
+    }
+
+    /**
+     * @return the key of the current epoch followed by the key of the previous epoch
+     */
+    public List<byte[]> getKeys()
+    {
+        long epoch = currentEpoch();
+        return List.of(getKey(epoch), getKey(epoch - 1));
+    }
+
+    protected long currentEpoch()
+    {
+        return System.currentTimeMillis() / rotationPeriod;
+    }
+
+    private byte[] getKey(long epoch)
+    {
+        byte[] key = keys.get(epoch);
+        if (key != null)
+            return key;
+
+        key = loadKey(epoch);
+        byte[] existing = keys.putIfAbsent(epoch, key);
+        if (existing != null)
+            return existing;
+
+        // Forget the keys that are too old to be used.
+        keys.keySet().removeIf(e -> e < epoch - 1);
+        return key;
+    }
+
+    private byte[] loadKey(long epoch)
+    {
+        String storeKey = name + ":" + epoch;
+        try
+        {
+            byte[] key = store.get(storeKey);
+            if (key != null && key.length == keyLength)
+                return key;
+
+            key = new byte[keyLength];
+            random.nextBytes(key);
+            // The key must be available for the current and the next epoch.
+            long ttl = 2 * rotationPeriod;
+            if (store.putIfAbsent(storeKey, key, ttl))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Generated key {} in {}", storeKey, store);
+                return key;
+            }
+
+            // Another node generated the key concurrently.
+            byte[] stored = store.get(storeKey);
+            if (stored != null && stored.length == keyLength)
+                return stored;
+            LOG.warn("Invalid key {} in {}, using a local key", storeKey, store);
+            return key;
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Could not load key {} from {}, using a local key", storeKey, store, x);
+            byte[] key = new byte[keyLength];
+            random.nextBytes(key);
+            return key;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,period=%d]", getClass().getSimpleName(), hashCode(), name, rotationPeriod);
+    }
+}
@@ -0,0 +1,110 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+
+import org.eclipse.jetty.util.NanoTime;
+
+/**
+ * <p>A store for the secrets that allow TLS sessions to be resumed, such as
+ * TLS session ticket keys and QUIC address validation token keys.</p>
+ * <p>Implementations backed by an external store (for example, Redis) can be
+ * shared across the nodes of a cluster, so that clients resuming against any
+ * node get an abbreviated handshake, regardless of the node that issued the
+ * session ticket or token.</p>
+ * <p>The values are secret material, so implementations must store them securely,
+ * and should restrict the access to the store to the nodes of the cluster.</p>
+ *
+ * @see ResumptionKeys
+ */
+public interface ResumptionStore
+{
+    /**
+     * @param key the key of the value
+     * @return the value associated with the given key, or null if there is no value or it expired
+     */
+    public byte[] get(String key);
+
+    /**
+     * <p>Associates the given value with the given key, only if there is no value
+     * already associated with the key, so that concurrent nodes agree on one value.</p>
+     *
+     * @param key the key of the value
+     * @param value the value to store
+     * @param ttl the time to live of the value, in milliseconds
+     * @return whether the value was stored
+     */
+    public boolean putIfAbsent(String key, byte[] value, long ttl);
+
+    /**
+     * @param key the key of the value to remove
+     */
+    public void remove(String key);
+
+    /**
+     * <p>A {@link ResumptionStore} that keeps the values in memory,
+     * so it can only be shared by connectors of the same JVM.</p>
+     */
+    public static class InMemory implements ResumptionStore
+    {
+        private final Map<String, Entry> entries = new ConcurrentHashMap<>();
+
+        @Override
+        public byte[] get(String key)
+        {
+            Entry entry = entries.get(key);
+            if (entry == null)
+                return null;
+            if (entry.isExpired())
+            {
+                entries.remove(key, entry);
+                return null;
+            }
+            return entry.value;
+        }
+
+        @Override
+        public boolean putIfAbsent(String key, byte[] value, long ttl)
+        {
+            entries.values().removeIf(Entry::isExpired);
+            Entry entry = new Entry(value, ttl);
+            return entries.putIfAbsent(key, entry) == null;
+        }
+
+        @Override
+        public void remove(String key)
+        {
+            entries.remove(key);
+        }
+
+        private static class Entry
+        {
+            private final long expireNanoTime;
+            private final byte[] value;
+
+            private Entry(byte[] value, long ttl)
+            {
+                this.expireNanoTime = NanoTime.now() + ttl * 1_000_000L;
+                this.value = value;
+            }
+
+            private boolean isExpired()
+            {
+                return NanoTime.isBeforeOrSame(expireNanoTime, NanoTime.now());
+            }
+        }
+    }
+}
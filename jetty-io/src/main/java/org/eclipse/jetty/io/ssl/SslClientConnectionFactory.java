@@ -16,6 +16,7 @@ package org.eclipse.jetty.io.ssl;
 import java.io.IOException;
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
+import java.util.List;
 import java.util.Map;
 import java.util.Objects;
 import java.util.concurrent.Executor;
@@ -31,6 +32,8 @@ import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
 
 /**
  * <p>A ClientConnectionFactory that creates client-side {@link SslConnection} instances.</p>
@@ -38,6 +41,12 @@ import org.eclipse.jetty.util.ssl.SslContextFactory;
 public class SslClientConnectionFactory implements ClientConnectionFactory
 {
     public static final String SSL_ENGINE_CONTEXT_KEY = "org.eclipse.jetty.client.ssl.engine";
+    /**
+     * <p>The context key of the {@code ECHConfigList} of the server, as a {@code byte[]},
+     * to use for TLS Encrypted Client Hello.</p>
+     */
+    public static final String ECH_CONFIG_LIST_CONTEXT_KEY = "org.eclipse.jetty.client.ssl.echConfigList";
+    private static final Logger LOG = LoggerFactory.getLogger(SslClientConnectionFactory.class);
 
     private final SslContextFactory sslContextFactory;
     private final ByteBufferPool byteBufferPool;
@@ -46,6 +55,7 @@ public class SslClientConnectionFactory implements ClientConnectionFactory
     private boolean _directBuffersForEncryption = true;
     private boolean _directBuffersForDecryption = true;
     private boolean _requireCloseMessage;
+    private volatile List<ECHProcessor.Client> _echProcessors;
 
     public SslClientConnectionFactory(SslContextFactory sslContextFactory, ByteBufferPool byteBufferPool, Executor executor, ClientConnectionFactory connectionFactory)
     {
@@ -117,6 +127,7 @@ public class SslClientConnectionFactory implements ClientConnectionFactory
             engine = sslContextFactory.newSSLEngine();
         }
         engine.setUseClientMode(true);
+        configureECH(engine, context);
         context.put(SSL_ENGINE_CONTEXT_KEY, engine);
 
         SslConnection sslConnection = newSslConnection(byteBufferPool, executor, endPoint, engine);
@@ -130,6 +141,30 @@ public class SslClientConnectionFactory implements ClientConnectionFactory
         return sslConnection;
     }
 
+    private void configureECH(SSLEngine engine, Map<String, Object> context)
+    {
+        byte[] echConfigList = (byte[])context.get(ECH_CONFIG_LIST_CONTEXT_KEY);
+        boolean grease = sslContextFactory instanceof SslContextFactory.Client && ((SslContextFactory.Client)sslContextFactory).isECHGrease();
+        if (echConfigList == null && !grease)
+            return;
+
+        List<ECHProcessor.Client> processors = _echProcessors;
+        if (processors == null)
+            _echProcessors = processors = ECHProcessor.load(ECHProcessor.Client.class);
+        for (ECHProcessor.Client processor : processors)
+        {
+            if (processor.appliesTo(engine))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} configuring ECH for {}", processor, engine);
+                processor.configure(engine, echConfigList, grease);
+                return;
+            }
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("No ECHProcessor for {}", engine);
+    }
+
     protected SslConnection newSslConnection(ByteBufferPool byteBufferPool, Executor executor, EndPoint endPoint, SSLEngine engine)
     {
         return new SslConnection(byteBufferPool, executor, endPoint, engine, isDirectBuffersForEncryption(), isDirectBuffersForDecryption());
@@ -144,6 +179,8 @@ public class SslClientConnectionFactory implements ClientConnectionFactory
             sslConnection.setRenegotiationAllowed(sslContextFactory.isRenegotiationAllowed());
             sslConnection.setRenegotiationLimit(sslContextFactory.getRenegotiationLimit());
             sslConnection.setRequireCloseMessage(isRequireCloseMessage());
+            if (sslContextFactory.isKeyLogEnabled())
+                sslConnection.addHandshakeListener(new SslKeyLogListener(sslContextFactory));
             ContainerLifeCycle client = (ContainerLifeCycle)context.get(ClientConnectionFactory.CLIENT_CONTEXT_KEY);
             if (client != null)
                 client.getBeans(SslHandshakeListener.class).forEach(sslConnection::addHandshakeListener);
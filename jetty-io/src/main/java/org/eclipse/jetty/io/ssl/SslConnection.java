@@ -37,6 +37,7 @@ import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.RetainableByteBuffer;
 import org.eclipse.jetty.io.RetainableByteBufferPool;
+import org.eclipse.jetty.io.TcpInfo;
 import org.eclipse.jetty.io.WriteFlusher;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
@@ -80,6 +81,7 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
 {
     private static final Logger LOG = LoggerFactory.getLogger(SslConnection.class);
     private static final String TLS_1_3 = "TLSv1.3";
+    private static final int MAX_CLIENT_HELLO_LENGTH = 64 * 1024;
 
     private enum HandshakeState
     {
@@ -121,6 +123,10 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
     private int _renegotiationLimit = -1;
     private boolean _closedOutbound;
     private boolean _requireCloseMessage;
+    private boolean _tlsFingerprinting;
+    private boolean _clientHelloParsed;
+    private byte[] _clientHello;
+    private volatile TlsFingerprint _tlsFingerprint;
     private FlushState _flushState = FlushState.IDLE;
     private FillState _fillState = FillState.IDLE;
     private boolean _underflown;
@@ -278,6 +284,82 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
         _requireCloseMessage = requireCloseMessage;
     }
 
+    /**
+     * @return whether the {@link TlsFingerprint} of the client is computed
+     */
+    public boolean isTlsFingerprinting()
+    {
+        return _tlsFingerprinting;
+    }
+
+    /**
+     * <p>Sets whether the {@link TlsFingerprint} of the client is computed from
+     * the ClientHello message received during the TLS handshake.</p>
+     * <p>This option is only useful on servers, and must be set before the connection is opened.</p>
+     *
+     * @param tlsFingerprinting whether the TLS fingerprint of the client is computed
+     * @see #getTlsFingerprint()
+     */
+    public void setTlsFingerprinting(boolean tlsFingerprinting)
+    {
+        _tlsFingerprinting = tlsFingerprinting;
+    }
+
+    /**
+     * @return the TLS fingerprint of the client, or null if TLS fingerprinting is disabled,
+     * the ClientHello message has not been received yet or it could not be parsed
+     * @see #setTlsFingerprinting(boolean)
+     */
+    public TlsFingerprint getTlsFingerprint()
+    {
+        return _tlsFingerprint;
+    }
+
+    private void captureClientHello(ByteBuffer encrypted, int position, int consumed)
+    {
+        if (consumed <= 0)
+            return;
+
+        int length = _clientHello == null ? 0 : _clientHello.length;
+        byte[] clientHello = new byte[length + consumed];
+        if (_clientHello != null)
+            System.arraycopy(_clientHello, 0, clientHello, 0, length);
+        ByteBuffer bytes = encrypted.duplicate();
+        bytes.limit(position + consumed).position(position);
+        bytes.get(clientHello, length, consumed);
+
+        try
+        {
+            TlsFingerprint fingerprint = TlsFingerprint.from(ByteBuffer.wrap(clientHello));
+            if (fingerprint != null)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("TLS fingerprint {} for {}", fingerprint, this);
+                _tlsFingerprint = fingerprint;
+                _clientHelloParsed = true;
+                _clientHello = null;
+            }
+            else if (clientHello.length > MAX_CLIENT_HELLO_LENGTH)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("ClientHello too large for {}", this);
+                _clientHelloParsed = true;
+                _clientHello = null;
+            }
+            else
+            {
+                _clientHello = clientHello;
+            }
+        }
+        catch (RuntimeException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not parse ClientHello for {}", this, x);
+            _clientHelloParsed = true;
+            _clientHello = null;
+        }
+    }
+
     private boolean isHandshakeInitial()
     {
         return _handshake.get() == HandshakeState.INITIAL;
@@ -288,7 +370,10 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
         return _handshake.get() == HandshakeState.SUCCEEDED;
     }
 
-    private boolean isHandshakeComplete()
+    /**
+     * @return whether the TLS handshake has completed, either successfully or not
+     */
+    public boolean isHandshakeComplete()
     {
         HandshakeState state = _handshake.get();
         return state == HandshakeState.SUCCEEDED || state == HandshakeState.FAILED;
@@ -534,6 +619,12 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
             return getEndPoint().getRemoteSocketAddress();
         }
 
+        @Override
+        public TcpInfo getTcpInfo()
+        {
+            return getEndPoint().getTcpInfo();
+        }
+
         @Override
         public WriteFlusher getWriteFlusher()
         {
@@ -714,6 +805,7 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
                             // Let's unwrap even if we have no net data because in that
                             // case we want to fall through to the handshake handling
                             int pos = BufferUtil.flipToFill(appIn);
+                            int encryptedPos = _encryptedInput.getBuffer().position();
                             SSLEngineResult unwrapResult;
                             try
                             {
@@ -724,6 +816,8 @@ public class SslConnection extends AbstractConnection implements Connection.Upgr
                             {
                                 BufferUtil.flipToFlush(appIn, pos);
                             }
+                            if (_tlsFingerprinting && !_clientHelloParsed && !_sslEngine.getUseClientMode())
+                                captureClientHello(_encryptedInput.getBuffer(), encryptedPos, unwrapResult.bytesConsumed());
                             if (LOG.isDebugEnabled())
                                 LOG.debug("unwrap net_filled={} {} encryptedBuffer={} unwrapBuffer={} appBuffer={}",
                                     netFilled,
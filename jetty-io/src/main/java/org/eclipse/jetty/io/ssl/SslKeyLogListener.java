@@ -0,0 +1,38 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+
+/**
+ * <p>A {@link SslHandshakeListener} that logs the TLS key material of successful
+ * handshakes via {@link SslContextFactory#logKeys(javax.net.ssl.SSLSession)}.</p>
+ * <p>This listener is added automatically to TLS connections when
+ * {@link SslContextFactory#isKeyLogEnabled() key logging is enabled}.</p>
+ */
+public class SslKeyLogListener implements SslHandshakeListener
+{
+    private final SslContextFactory sslContextFactory;
+
+    public SslKeyLogListener(SslContextFactory sslContextFactory)
+    {
+        this.sslContextFactory = sslContextFactory;
+    }
+
+    @Override
+    public void handshakeSucceeded(Event event)
+    {
+        sslContextFactory.logKeys(event.getSSLEngine().getSession());
+    }
+}
@@ -0,0 +1,478 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.BufferUnderflowException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.List;
+import java.util.stream.Collectors;
+
+/**
+ * <p>The fingerprint of a TLS client, computed from the parameters of the ClientHello message
+ * that the client sends to start the TLS handshake.</p>
+ * <p>Clients built with the same TLS stack and configuration send the same ClientHello parameters,
+ * so the fingerprint identifies the client stack rather than the client itself, which may be used
+ * for example to detect bots that claim to be browsers.</p>
+ * <p>The fingerprint is available in the following formats:</p>
+ * <ul>
+ * <li><a href="https://github.com/salesforce/ja3">JA3</a>, see {@link #getJa3()} and {@link #getJa3Hash()}</li>
+ * <li><a href="https://github.com/FoxIO-LLC/ja4">JA4</a>, see {@link #getJa4()}</li>
+ * </ul>
+ * <p>GREASE values (RFC 8701) are ignored by both formats.</p>
+ *
+ * @see SslConnection#getTlsFingerprint()
+ */
+public class TlsFingerprint
+{
+    private static final int HANDSHAKE_RECORD_TYPE = 0x16;
+    private static final int CLIENT_HELLO_TYPE = 0x01;
+    private static final int SERVER_NAME = 0x0000;
+    private static final int SUPPORTED_GROUPS = 0x000A;
+    private static final int EC_POINT_FORMATS = 0x000B;
+    private static final int SIGNATURE_ALGORITHMS = 0x000D;
+    private static final int APPLICATION_LAYER_PROTOCOL_NEGOTIATION = 0x0010;
+    private static final int SUPPORTED_VERSIONS = 0x002B;
+    private static final String EMPTY_HASH = "000000000000";
+
+    /**
+     * <p>Parses the ClientHello message contained in the given TLS records.</p>
+     * <p>The ClientHello message may span multiple TLS records.</p>
+     *
+     * @param records the TLS records sent by the client, the buffer position is not modified
+     * @return the fingerprint of the client, or null if more bytes are needed to parse the ClientHello message
+     * @throws IllegalArgumentException if the bytes are not a valid ClientHello message
+     */
+    public static TlsFingerprint from(ByteBuffer records)
+    {
+        ByteBuffer input = records.slice();
+        ByteArrayOutputStream handshake = new ByteArrayOutputStream();
+        while (true)
+        {
+            if (input.remaining() < 5)
+                return null;
+            int recordType = input.get() & 0xFF;
+            int majorVersion = input.get() & 0xFF;
+            input.get();
+            int recordLength = input.getShort() & 0xFFFF;
+            if (recordType != HANDSHAKE_RECORD_TYPE || majorVersion != 3)
+                throw new IllegalArgumentException("Not a TLS handshake record");
+            if (input.remaining() < recordLength)
+                return null;
+            for (int i = 0; i < recordLength; i++)
+            {
+                handshake.write(input.get());
+            }
+
+            byte[] bytes = handshake.toByteArray();
+            if (bytes.length < 4)
+                continue;
+            if ((bytes[0] & 0xFF) != CLIENT_HELLO_TYPE)
+                throw new IllegalArgumentException("Not a ClientHello message");
+            int messageLength = ((bytes[1] & 0xFF) << 16) | ((bytes[2] & 0xFF) << 8) | (bytes[3] & 0xFF);
+            if (bytes.length >= 4 + messageLength)
+                return parse(ByteBuffer.wrap(bytes, 4, messageLength).slice());
+        }
+    }
+
+    private static TlsFingerprint parse(ByteBuffer hello)
+    {
+        try
+        {
+            int legacyVersion = hello.getShort() & 0xFFFF;
+            // Skip the random.
+            slice(hello, 32);
+            // Skip the session id.
+            slice(hello, hello.get() & 0xFF);
+            List<Integer> cipherSuites = readShorts(slice(hello, hello.getShort() & 0xFFFF));
+            // Skip the compression methods.
+            slice(hello, hello.get() & 0xFF);
+
+            List<Integer> extensions = new ArrayList<>();
+            List<Integer> versions = List.of();
+            List<Integer> supportedGroups = List.of();
+            List<Integer> pointFormats = List.of();
+            List<Integer> signatureAlgorithms = List.of();
+            List<String> applicationProtocols = List.of();
+            String serverName = null;
+            if (hello.hasRemaining())
+            {
+                ByteBuffer data = slice(hello, hello.getShort() & 0xFFFF);
+                while (data.hasRemaining())
+                {
+                    int type = data.getShort() & 0xFFFF;
+                    ByteBuffer extension = slice(data, data.getShort() & 0xFFFF);
+                    extensions.add(type);
+                    switch (type)
+                    {
+                        case SERVER_NAME:
+                            serverName = readServerName(extension);
+                            break;
+                        case SUPPORTED_GROUPS:
+                            supportedGroups = readShorts(slice(extension, extension.getShort() & 0xFFFF));
+                            break;
+                        case EC_POINT_FORMATS:
+                            pointFormats = readBytes(slice(extension, extension.get() & 0xFF));
+                            break;
+                        case SIGNATURE_ALGORITHMS:
+                            signatureAlgorithms = readShorts(slice(extension, extension.getShort() & 0xFFFF));
+                            break;
+                        case APPLICATION_LAYER_PROTOCOL_NEGOTIATION:
+                            applicationProtocols = readProtocols(slice(extension, extension.getShort() & 0xFFFF));
+                            break;
+                        case SUPPORTED_VERSIONS:
+                            versions = readShorts(slice(extension, extension.get() & 0xFF));
+                            break;
+                        default:
+                            break;
+                    }
+                }
+            }
+            return new TlsFingerprint(legacyVersion, versions, cipherSuites, extensions, supportedGroups,
+                pointFormats, signatureAlgorithms, serverName, applicationProtocols);
+        }
+        catch (BufferUnderflowException x)
+        {
+            throw new IllegalArgumentException("Invalid ClientHello message", x);
+        }
+    }
+
+    private static ByteBuffer slice(ByteBuffer buffer, int length)
+    {
+        if (buffer.remaining() < length)
+            throw new BufferUnderflowException();
+        ByteBuffer slice = buffer.slice();
+        slice.limit(length);
+        buffer.position(buffer.position() + length);
+        return slice;
+    }
+
+    private static List<Integer> readShorts(ByteBuffer buffer)
+    {
+        List<Integer> values = new ArrayList<>();
+        while (buffer.remaining() >= 2)
+        {
+            values.add(buffer.getShort() & 0xFFFF);
+        }
+        return values;
+    }
+
+    private static List<Integer> readBytes(ByteBuffer buffer)
+    {
+        List<Integer> values = new ArrayList<>();
+        while (buffer.hasRemaining())
+        {
+            values.add(buffer.get() & 0xFF);
+        }
+        return values;
+    }
+
+    private static List<String> readProtocols(ByteBuffer buffer)
+    {
+        List<String> protocols = new ArrayList<>();
+        while (buffer.hasRemaining())
+        {
+            ByteBuffer protocol = slice(buffer, buffer.get() & 0xFF);
+            protocols.add(StandardCharsets.ISO_8859_1.decode(protocol).toString());
+        }
+        return protocols;
+    }
+
+    private static String readServerName(ByteBuffer extension)
+    {
+        if (!extension.hasRemaining())
+            return "";
+        ByteBuffer list = slice(extension, extension.getShort() & 0xFFFF);
+        while (list.hasRemaining())
+        {
+            int nameType = list.get() & 0xFF;
+            ByteBuffer name = slice(list, list.getShort() & 0xFFFF);
+            if (nameType == 0)
+                return StandardCharsets.US_ASCII.decode(name).toString();
+        }
+        return "";
+    }
+
+    /**
+     * @param value a TLS parameter value
+     * @return whether the value is a GREASE value, as defined by RFC 8701
+     */
+    static boolean isGrease(int value)
+    {
+        return (value & 0x0F0F) == 0x0A0A && (value >> 8) == (value & 0xFF);
+    }
+
+    private final int _legacyVersion;
+    private final List<Integer> _versions;
+    private final List<Integer> _cipherSuites;
+    private final List<Integer> _extensions;
+    private final List<Integer> _supportedGroups;
+    private final List<Integer> _pointFormats;
+    private final List<Integer> _signatureAlgorithms;
+    private final String _serverName;
+    private final List<String> _applicationProtocols;
+    private final String _ja3;
+    private final String _ja3Hash;
+    private final String _ja4;
+
+    private TlsFingerprint(int legacyVersion, List<Integer> versions, List<Integer> cipherSuites, List<Integer> extensions,
+                           List<Integer> supportedGroups, List<Integer> pointFormats, List<Integer> signatureAlgorithms,
+                           String serverName, List<String> applicationProtocols)
+    {
+        _legacyVersion = legacyVersion;
+        _versions = Collections.unmodifiableList(versions);
+        _cipherSuites = Collections.unmodifiableList(cipherSuites);
+        _extensions = Collections.unmodifiableList(extensions);
+        _supportedGroups = Collections.unmodifiableList(supportedGroups);
+        _pointFormats = Collections.unmodifiableList(pointFormats);
+        _signatureAlgorithms = Collections.unmodifiableList(signatureAlgorithms);
+        _serverName = serverName;
+        _applicationProtocols = Collections.unmodifiableList(applicationProtocols);
+        _ja3 = computeJa3();
+        _ja3Hash = toHex(digest("MD5", _ja3));
+        _ja4 = computeJa4();
+    }
+
+    /**
+     * @return the {@code legacy_version} field of the ClientHello message
+     */
+    public int getLegacyVersion()
+    {
+        return _legacyVersion;
+    }
+
+    /**
+     * @return the TLS versions of the {@code supported_versions} extension, possibly empty
+     */
+    public List<Integer> getSupportedVersions()
+    {
+        return _versions;
+    }
+
+    /**
+     * @return the cipher suites offered by the client, in the order sent by the client
+     */
+    public List<Integer> getCipherSuites()
+    {
+        return _cipherSuites;
+    }
+
+    /**
+     * @return the types of the extensions sent by the client, in the order sent by the client
+     */
+    public List<Integer> getExtensions()
+    {
+        return _extensions;
+    }
+
+    /**
+     * @return the groups of the {@code supported_groups} extension, possibly empty
+     */
+    public List<Integer> getSupportedGroups()
+    {
+        return _supportedGroups;
+    }
+
+    /**
+     * @return the formats of the {@code ec_point_formats} extension, possibly empty
+     */
+    public List<Integer> getPointFormats()
+    {
+        return _pointFormats;
+    }
+
+    /**
+     * @return the algorithms of the {@code signature_algorithms} extension, possibly empty
+     */
+    public List<Integer> getSignatureAlgorithms()
+    {
+        return _signatureAlgorithms;
+    }
+
+    /**
+     * @return the host name of the {@code server_name} extension, or null if the client did not send SNI
+     */
+    public String getServerName()
+    {
+        return _serverName;
+    }
+
+    /**
+     * @return the protocols of the {@code application_layer_protocol_negotiation} extension, possibly empty
+     */
+    public List<String> getApplicationProtocols()
+    {
+        return _applicationProtocols;
+    }
+
+    /**
+     * @return the JA3 fingerprint string, for example {@code 771,4865-4866-4867,0-23-65281,29-23-24,0}
+     */
+    public String getJa3()
+    {
+        return _ja3;
+    }
+
+    /**
+     * @return the MD5 hash of the JA3 fingerprint string, as lowercase hexadecimal
+     */
+    public String getJa3Hash()
+    {
+        return _ja3Hash;
+    }
+
+    /**
+     * @return the JA4 fingerprint, for example {@code t13d1516h2_8daaf6152771_e5627efa2ab1}
+     */
+    public String getJa4()
+    {
+        return _ja4;
+    }
+
+    private String computeJa3()
+    {
+        return _legacyVersion + "," +
+            join(_cipherSuites, false, "-") + "," +
+            join(_extensions, false, "-") + "," +
+            join(_supportedGroups, false, "-") + "," +
+            join(_pointFormats, false, "-");
+    }
+
+    private String computeJa4()
+    {
+        int version = _versions.stream()
+            .filter(v -> !isGrease(v))
+            .max(Integer::compare)
+            .orElse(_legacyVersion);
+        List<Integer> cipherSuites = _cipherSuites.stream()
+            .filter(c -> !isGrease(c))
+            .sorted()
+            .collect(Collectors.toList());
+        List<Integer> extensions = _extensions.stream()
+            .filter(e -> !isGrease(e))
+            .collect(Collectors.toList());
+
+        StringBuilder ja4 = new StringBuilder();
+        ja4.append('t');
+        ja4.append(toJa4Version(version));
+        ja4.append(_extensions.contains(SERVER_NAME) ? 'd' : 'i');
+        ja4.append(String.format("%02d", Math.min(99, cipherSuites.size())));
+        ja4.append(String.format("%02d", Math.min(99, extensions.size())));
+        ja4.append(toJa4Protocol(_applicationProtocols.isEmpty() ? "" : _applicationProtocols.get(0)));
+
+        ja4.append('_');
+        ja4.append(cipherSuites.isEmpty() ? EMPTY_HASH : truncatedHash(join(cipherSuites, true, ",")));
+
+        ja4.append('_');
+        List<Integer> sortedExtensions = extensions.stream()
+            .filter(e -> e != SERVER_NAME && e != APPLICATION_LAYER_PROTOCOL_NEGOTIATION)
+            .sorted()
+            .collect(Collectors.toList());
+        if (sortedExtensions.isEmpty())
+        {
+            ja4.append(EMPTY_HASH);
+        }
+        else
+        {
+            String input = join(sortedExtensions, true, ",");
+            if (!_signatureAlgorithms.isEmpty())
+                input += "_" + join(_signatureAlgorithms, true, ",");
+            ja4.append(truncatedHash(input));
+        }
+        return ja4.toString();
+    }
+
+    private static String toJa4Version(int version)
+    {
+        switch (version)
+        {
+            case 0x0304:
+                return "13";
+            case 0x0303:
+                return "12";
+            case 0x0302:
+                return "11";
+            case 0x0301:
+                return "10";
+            case 0x0300:
+                return "s3";
+            default:
+                return "00";
+        }
+    }
+
+    private static String toJa4Protocol(String protocol)
+    {
+        if (protocol.isEmpty())
+            return "00";
+        char first = protocol.charAt(0);
+        char last = protocol.charAt(protocol.length() - 1);
+        if (isAlphaNumeric(first) && isAlphaNumeric(last))
+            return "" + first + last;
+        String firstHex = String.format("%02x", (int)first);
+        String lastHex = String.format("%02x", (int)last);
+        return "" + firstHex.charAt(0) + lastHex.charAt(1);
+    }
+
+    private static boolean isAlphaNumeric(char c)
+    {
+        return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z');
+    }
+
+    private static String join(List<Integer> values, boolean hex, String separator)
+    {
+        return values.stream()
+            .filter(v -> !isGrease(v))
+            .map(v -> hex ? String.format("%04x", v) : String.valueOf(v))
+            .collect(Collectors.joining(separator));
+    }
+
+    private static String truncatedHash(String input)
+    {
+        return toHex(digest("SHA-256", input)).substring(0, 12);
+    }
+
+    private static byte[] digest(String algorithm, String input)
+    {
+        try
+        {
+            return MessageDigest.getInstance(algorithm).digest(input.getBytes(StandardCharsets.US_ASCII));
+        }
+        catch (NoSuchAlgorithmException x)
+        {
+            throw new IllegalStateException(x);
+        }
+    }
+
+    private static String toHex(byte[] bytes)
+    {
+        StringBuilder builder = new StringBuilder(bytes.length * 2);
+        for (byte b : bytes)
+        {
+            builder.append(String.format("%02x", b & 0xFF));
+        }
+        return builder.toString();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{ja3=%s,ja4=%s}", getClass().getSimpleName(), hashCode(), _ja3Hash, _ja4);
+    }
+}
@@ -0,0 +1,142 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.ssl;
+
+import java.util.concurrent.atomic.LongAdder;
+import javax.net.ssl.SSLSession;
+
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+
+/**
+ * <p>A {@link Connection.Listener} that tracks how many TLS handshakes
+ * resumed a previous TLS session, rather than performing a full handshake.</p>
+ * <p>Adding an instance of this class as a bean to a connector will track
+ * the TLS handshakes of the {@link SslConnection}s of that connector;
+ * QUIC connectors report the handshakes of their QUIC sessions as well.</p>
+ * <p>A TLS handshake of a {@link SslConnection} is considered resumed when
+ * the negotiated {@link SSLSession} was created before the connection was opened.</p>
+ */
+@ManagedObject("Tracks TLS session resumption")
+public class TlsResumptionStatistics extends AbstractLifeCycle implements Connection.Listener
+{
+    private final LongAdder _handshakes = new LongAdder();
+    private final LongAdder _resumed = new LongAdder();
+    private final LongAdder _failed = new LongAdder();
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void reset()
+    {
+        _handshakes.reset();
+        _resumed.reset();
+        _failed.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        reset();
+    }
+
+    @Override
+    public void onOpened(Connection connection)
+    {
+        if (!isStarted() || !(connection instanceof SslConnection))
+            return;
+        long created = connection.getEndPoint().getCreatedTimeStamp();
+        ((SslConnection)connection).addHandshakeListener(new SslHandshakeListener()
+        {
+            @Override
+            public void handshakeSucceeded(Event event)
+            {
+                SSLSession session = event.getSSLEngine().getSession();
+                onHandshakeSucceeded(session.getCreationTime() < created);
+            }
+
+            @Override
+            public void handshakeFailed(Event event, Throwable failure)
+            {
+                onHandshakeFailed();
+            }
+        });
+    }
+
+    @Override
+    public void onClosed(Connection connection)
+    {
+    }
+
+    /**
+     * <p>Records a successful TLS handshake.</p>
+     *
+     * @param resumed whether the handshake resumed a previous TLS session
+     */
+    public void onHandshakeSucceeded(boolean resumed)
+    {
+        _handshakes.increment();
+        if (resumed)
+            _resumed.increment();
+    }
+
+    /**
+     * <p>Records a failed TLS handshake.</p>
+     */
+    public void onHandshakeFailed()
+    {
+        _failed.increment();
+    }
+
+    @ManagedAttribute("The number of successful TLS handshakes")
+    public long getHandshakes()
+    {
+        return _handshakes.sum();
+    }
+
+    @ManagedAttribute("The number of TLS handshakes that resumed a previous session")
+    public long getResumedHandshakes()
+    {
+        return _resumed.sum();
+    }
+
+    @ManagedAttribute("The number of TLS handshakes that did not resume a previous session")
+    public long getFullHandshakes()
+    {
+        return getHandshakes() - getResumedHandshakes();
+    }
+
+    @ManagedAttribute("The number of failed TLS handshakes")
+    public long getFailedHandshakes()
+    {
+        return _failed.sum();
+    }
+
+    /**
+     * @return the ratio of resumed handshakes over successful handshakes, between 0 and 1
+     */
+    @ManagedAttribute("The ratio of resumed TLS handshakes")
+    public double getResumptionRate()
+    {
+        long handshakes = getHandshakes();
+        return handshakes == 0 ? 0.0D : (double)getResumedHandshakes() / handshakes;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{handshakes=%d,resumed=%d,failed=%d}", getClass().getSimpleName(), hashCode(), getHandshakes(), getResumedHandshakes(), getFailedHandshakes());
+    }
+}
@@ -0,0 +1,185 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNotSame;
+import static org.junit.jupiter.api.Assertions.assertSame;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class AdaptiveByteBufferPoolTest
+{
+    @Test
+    public void testDefaultBuckets()
+    {
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool(1024, 16 * 1024, 4, -1, -1);
+
+        assertThat(bufferPool.getBucketCapacities(false), contains(2048, 4096, 8192, 16384));
+
+        ByteBuffer buffer = bufferPool.acquire(100, false);
+        assertEquals(2048, buffer.capacity());
+        assertEquals(0, buffer.remaining());
+        bufferPool.release(buffer);
+        assertSame(buffer, bufferPool.acquire(2000, false));
+        assertEquals(1, bufferPool.getHits());
+        assertEquals(1, bufferPool.getMisses());
+
+        // Larger than the max capacity is not pooled.
+        ByteBuffer large = bufferPool.acquire(20 * 1024, false);
+        assertEquals(20 * 1024, large.capacity());
+        bufferPool.release(large);
+        assertEquals(0, bufferPool.getHeapMemory());
+    }
+
+    @Test
+    public void testInvalidCapacities()
+    {
+        assertThrows(IllegalArgumentException.class, () -> new AdaptiveByteBufferPool(1000, 1500, -1, -1, -1));
+    }
+
+    @Test
+    public void testAdaptToUsage()
+    {
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool(1024, 64 * 1024, 16, -1, -1);
+
+        for (int i = 0; i < 10; ++i)
+        {
+            bufferPool.release(bufferPool.acquire(3000, true));
+            bufferPool.release(bufferPool.acquire(40000, true));
+        }
+        bufferPool.adapt();
+
+        assertThat(bufferPool.getBucketCapacities(true), contains(3072, 40960));
+        // Heap buffers were not used, so their buckets did not change.
+        assertThat(bufferPool.getBucketCapacities(false).size(), is(7));
+
+        // The buffers of the retired buckets have been discarded.
+        assertEquals(0, bufferPool.getDirectMemory());
+
+        ByteBuffer small = bufferPool.acquire(2500, true);
+        assertEquals(3072, small.capacity());
+        bufferPool.release(small);
+        assertThat(bufferPool.acquire(2900, true), sameInstance(small));
+
+        ByteBuffer medium = bufferPool.acquire(10000, true);
+        assertEquals(40960, medium.capacity());
+    }
+
+    @Test
+    public void testReleaseToRetiredBucketDiscards()
+    {
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool(1024, 64 * 1024, 16, -1, -1);
+
+        ByteBuffer buffer = bufferPool.acquire(3000, false);
+        assertEquals(4096, buffer.capacity());
+        bufferPool.adapt();
+        assertThat(bufferPool.getBucketCapacities(false), contains(3072));
+
+        bufferPool.release(buffer);
+        assertEquals(0, bufferPool.getHeapMemory());
+        assertNotSame(buffer, bufferPool.acquire(3000, false));
+    }
+
+    @Test
+    public void testMemoryBudgetEvicts()
+    {
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool(1024, 8 * 1024, 4, 10 * 1024, -1);
+
+        ByteBuffer buffer1 = bufferPool.acquire(8 * 1024, false);
+        ByteBuffer buffer2 = bufferPool.acquire(2 * 1024, false);
+        ByteBuffer buffer3 = bufferPool.acquire(4 * 1024, false);
+        bufferPool.release(buffer1);
+        bufferPool.release(buffer2);
+        assertEquals(10 * 1024, bufferPool.getHeapMemory());
+
+        // There is no room for buffer3, the least recently used buffer1 is evicted.
+        bufferPool.release(buffer3);
+        assertEquals(6 * 1024, bufferPool.getHeapMemory());
+        assertEquals(1, bufferPool.getEvictions());
+        assertNotSame(buffer1, bufferPool.acquire(8 * 1024, false));
+        assertSame(buffer2, bufferPool.acquire(2 * 1024, false));
+        assertSame(buffer3, bufferPool.acquire(4 * 1024, false));
+    }
+
+    @Test
+    public void testLeakDetection() throws Exception
+    {
+        List<ByteBuffer> leaks = new CopyOnWriteArrayList<>();
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool()
+        {
+            @Override
+            protected void leaked(ByteBuffer buffer, Throwable acquisition)
+            {
+                assertTrue(acquisition.getStackTrace().length > 0);
+                leaks.add(buffer);
+            }
+        };
+        bufferPool.setLeakTimeout(Duration.ofMillis(100));
+
+        ByteBuffer leaked = bufferPool.acquire(1024, true);
+        ByteBuffer released = bufferPool.acquire(1024, true);
+        ByteBuffer removed = bufferPool.acquire(1024, false);
+        bufferPool.release(released);
+        bufferPool.remove(removed);
+        assertEquals(1, bufferPool.getTrackedBuffers());
+
+        // Not leaked yet.
+        bufferPool.detectLeaks();
+        assertTrue(leaks.isEmpty());
+
+        Thread.sleep(200);
+        bufferPool.detectLeaks();
+        assertThat(leaks, contains(sameInstance(leaked)));
+        assertEquals(1, bufferPool.getLeakedBuffers());
+        assertEquals(0, bufferPool.getTrackedBuffers());
+
+        // Leaks are only reported once.
+        bufferPool.detectLeaks();
+        assertEquals(1, leaks.size());
+    }
+
+    @Test
+    public void testPeriodicLeakDetection() throws Exception
+    {
+        AdaptiveByteBufferPool bufferPool = new AdaptiveByteBufferPool();
+        bufferPool.setLeakTimeout(Duration.ofMillis(100));
+        bufferPool.start();
+        try
+        {
+            bufferPool.acquire(1024, false);
+            long end = System.nanoTime() + Duration.ofSeconds(5).toNanos();
+            while (bufferPool.getLeakedBuffers() == 0 && System.nanoTime() < end)
+            {
+                Thread.sleep(50);
+            }
+            assertEquals(1, bufferPool.getLeakedBuffers());
+        }
+        finally
+        {
+            bufferPool.stop();
+        }
+    }
+}
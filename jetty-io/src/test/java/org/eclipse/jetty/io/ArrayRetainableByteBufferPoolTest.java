@@ -19,6 +19,7 @@ import java.nio.ByteOrder;
 import java.util.ArrayList;
 import java.util.List;
 
+import org.eclipse.jetty.util.Pool;
 import org.hamcrest.Matchers;
 import org.junit.jupiter.api.Test;
 
@@ -420,4 +421,29 @@ public class ArrayRetainableByteBufferPoolTest
         assertThat(retain9, not(sameInstance(retain5)));
         retain9.release();
     }
+
+    @Test
+    public void testStripedStrategy()
+    {
+        ArrayRetainableByteBufferPool pool = new ArrayRetainableByteBufferPool(0, 10, 20, Integer.MAX_VALUE, -1L, -1L, Pool.StrategyType.STRIPED);
+
+        List<RetainableByteBuffer> buffers = new ArrayList<>();
+        for (int i = 0; i < 8; i++)
+        {
+            buffers.add(pool.acquire(10, true));
+        }
+        buffers.forEach(RetainableByteBuffer::release);
+        assertThat(pool.getDirectByteBufferCount(), is(8L));
+        assertThat(pool.getAvailableDirectByteBufferCount(), is(8L));
+
+        // All the released buffers are reused, whatever stripe they are in.
+        buffers.clear();
+        for (int i = 0; i < 8; i++)
+        {
+            buffers.add(pool.acquire(10, true));
+        }
+        assertThat(pool.getDirectByteBufferCount(), is(8L));
+        assertThat(pool.getAvailableDirectByteBufferCount(), is(0L));
+        buffers.forEach(RetainableByteBuffer::release);
+    }
 }
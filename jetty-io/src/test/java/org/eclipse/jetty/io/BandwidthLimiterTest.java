@@ -0,0 +1,69 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.util.concurrent.TimeUnit;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class BandwidthLimiterTest
+{
+    @Test
+    public void testBurstIsAvailableImmediately()
+    {
+        BandwidthLimiter limiter = new BandwidthLimiter(1024, 512);
+
+        assertThat(limiter.getAvailable(), is(512L));
+        assertThat(limiter.getDelay(), is(0L));
+    }
+
+    @Test
+    public void testDebtDelaysTransfer()
+    {
+        BandwidthLimiter limiter = new BandwidthLimiter(1000, 100);
+
+        // Reserve in excess of the burst: 900 bytes of debt at 1000 bytes/s.
+        limiter.reserve(1000);
+
+        assertThat(limiter.getAvailable(), is(0L));
+        long delay = limiter.getDelay();
+        assertThat(delay, greaterThan(TimeUnit.MILLISECONDS.toNanos(800)));
+        assertThat(delay, lessThanOrEqualTo(TimeUnit.MILLISECONDS.toNanos(901)));
+    }
+
+    @Test
+    public void testRefillIsCappedByBurst() throws Exception
+    {
+        BandwidthLimiter limiter = new BandwidthLimiter(100_000, 10);
+        limiter.reserve(10);
+
+        Thread.sleep(10);
+
+        assertThat(limiter.getAvailable(), is(10L));
+        assertThat(limiter.getDelay(), is(0L));
+    }
+
+    @Test
+    public void testInvalidArguments()
+    {
+        assertThrows(IllegalArgumentException.class, () -> new BandwidthLimiter(0, 1));
+        assertThrows(IllegalArgumentException.class, () -> new BandwidthLimiter(1, 0));
+    }
+}
@@ -0,0 +1,176 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+import org.eclipse.jetty.io.ssl.ECHClientHello;
+import org.eclipse.jetty.util.ssl.ECHConfig;
+import org.eclipse.jetty.util.ssl.ECHKey;
+import org.eclipse.jetty.util.ssl.HPKE;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class ECHClientHelloTest
+{
+    private static final int SERVER_NAME = 0x0000;
+    private static final int SUPPORTED_GROUPS = 0x000A;
+    private static final int ALPN = 0x0010;
+
+    private final byte[] random = new byte[32];
+    private final byte[] sessionId = new byte[]{9, 8, 7, 6};
+
+    @Test
+    public void testDecrypt() throws Exception
+    {
+        ECHKey key = ECHKey.generate(7, "public.example.com");
+        ECHKey other = ECHKey.generate(8, "other.example.com");
+        random[0] = 42;
+
+        Map<Integer, byte[]> innerExtensions = new LinkedHashMap<>();
+        innerExtensions.put(SERVER_NAME, "secret.example.com".getBytes(StandardCharsets.US_ASCII));
+        innerExtensions.put(ECHClientHello.ENCRYPTED_CLIENT_HELLO, new byte[]{1});
+        innerExtensions.put(ECHClientHello.ECH_OUTER_EXTENSIONS, new byte[]{2, 0x00, (byte)SUPPORTED_GROUPS});
+        innerExtensions.put(ALPN, "h2".getBytes(StandardCharsets.US_ASCII));
+
+        byte[] outerHello = outerClientHello(key.getConfig(), innerExtensions);
+        ECHClientHello clientHello = ECHClientHello.decrypt(ByteBuffer.wrap(outerHello), List.of(other, key));
+
+        assertThat(clientHello, notNullValue());
+        assertThat(clientHello.getKey(), is(key));
+
+        // The outer extensions are expanded, and the session id is copied from the outer ClientHello.
+        Map<Integer, byte[]> expected = new LinkedHashMap<>();
+        expected.put(SERVER_NAME, innerExtensions.get(SERVER_NAME));
+        expected.put(ECHClientHello.ENCRYPTED_CLIENT_HELLO, new byte[]{1});
+        expected.put(SUPPORTED_GROUPS, new byte[]{0, 2, 0, 0x1D});
+        expected.put(ALPN, innerExtensions.get(ALPN));
+        assertThat(clientHello.getInnerClientHello(), is(message(hello(sessionId, expected))));
+    }
+
+    @Test
+    public void testDecryptUnknownConfig() throws Exception
+    {
+        ECHKey key = ECHKey.generate(7, "public.example.com");
+        ECHKey other = ECHKey.generate(8, "other.example.com");
+
+        Map<Integer, byte[]> innerExtensions = new LinkedHashMap<>();
+        innerExtensions.put(ECHClientHello.ENCRYPTED_CLIENT_HELLO, new byte[]{1});
+        byte[] outerHello = outerClientHello(key.getConfig(), innerExtensions);
+
+        assertThat(ECHClientHello.decrypt(ByteBuffer.wrap(outerHello), List.of(other)), nullValue());
+    }
+
+    @Test
+    public void testNoExtension()
+    {
+        Map<Integer, byte[]> extensions = new LinkedHashMap<>();
+        extensions.put(SERVER_NAME, "example.com".getBytes(StandardCharsets.US_ASCII));
+        byte[] clientHello = message(hello(sessionId, extensions));
+
+        assertThat(ECHClientHello.decrypt(ByteBuffer.wrap(clientHello), List.of()), nullValue());
+    }
+
+    @Test
+    public void testInvalidClientHello()
+    {
+        assertThrows(IllegalArgumentException.class, () -> ECHClientHello.decrypt(ByteBuffer.wrap(new byte[]{1, 0, 0, 10, 3, 3}), List.of()));
+    }
+
+    private byte[] outerClientHello(ECHConfig config, Map<Integer, byte[]> innerExtensions) throws Exception
+    {
+        ByteArrayOutputStream encoded = new ByteArrayOutputStream();
+        encoded.writeBytes(hello(new byte[0], innerExtensions));
+        // Padding.
+        encoded.writeBytes(new byte[8]);
+        byte[] plainText = encoded.toByteArray();
+
+        ECHConfig.CipherSuite cipherSuite = config.getCipherSuites().get(0);
+        HPKE.Sender sender = config.newSenderContext(cipherSuite);
+        // The AAD is the outer ClientHello with a zeroed payload of the same length.
+        byte[] aad = hello(sessionId, outerExtensions(config, cipherSuite, sender.getEncapsulatedKey(), new byte[plainText.length + 16]));
+        byte[] payload = sender.seal(aad, plainText);
+        return message(hello(sessionId, outerExtensions(config, cipherSuite, sender.getEncapsulatedKey(), payload)));
+    }
+
+    private static Map<Integer, byte[]> outerExtensions(ECHConfig config, ECHConfig.CipherSuite cipherSuite, byte[] enc, byte[] payload)
+    {
+        ByteArrayOutputStream ech = new ByteArrayOutputStream();
+        ech.write(0);
+        writeShort(ech, cipherSuite.getKdfId());
+        writeShort(ech, cipherSuite.getAeadId());
+        ech.write(config.getConfigId());
+        writeShort(ech, enc.length);
+        ech.writeBytes(enc);
+        writeShort(ech, payload.length);
+        ech.writeBytes(payload);
+
+        Map<Integer, byte[]> extensions = new LinkedHashMap<>();
+        extensions.put(SERVER_NAME, config.getPublicName().getBytes(StandardCharsets.US_ASCII));
+        extensions.put(SUPPORTED_GROUPS, new byte[]{0, 2, 0, 0x1D});
+        extensions.put(ECHClientHello.ENCRYPTED_CLIENT_HELLO, ech.toByteArray());
+        return extensions;
+    }
+
+    private byte[] hello(byte[] sessionId, Map<Integer, byte[]> extensions)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        writeShort(output, 0x0303);
+        output.writeBytes(random);
+        output.write(sessionId.length);
+        output.writeBytes(sessionId);
+        // TLS_AES_128_GCM_SHA256.
+        writeShort(output, 2);
+        writeShort(output, 0x1301);
+        // Null compression.
+        output.write(1);
+        output.write(0);
+        ByteArrayOutputStream data = new ByteArrayOutputStream();
+        for (Map.Entry<Integer, byte[]> entry : extensions.entrySet())
+        {
+            writeShort(data, entry.getKey());
+            writeShort(data, entry.getValue().length);
+            data.writeBytes(entry.getValue());
+        }
+        writeShort(output, data.size());
+        output.writeBytes(data.toByteArray());
+        return output.toByteArray();
+    }
+
+    private static byte[] message(byte[] body)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        output.write(0x01);
+        output.write(body.length >>> 16);
+        writeShort(output, body.length);
+        output.writeBytes(body);
+        return output.toByteArray();
+    }
+
+    private static void writeShort(ByteArrayOutputStream output, int value)
+    {
+        output.write((value >>> 8) & 0xFF);
+        output.write(value & 0xFF);
+    }
+}
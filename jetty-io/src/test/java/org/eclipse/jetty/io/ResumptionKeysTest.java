@@ -0,0 +1,112 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.util.List;
+import java.util.concurrent.atomic.AtomicLong;
+
+import org.eclipse.jetty.io.ssl.ResumptionKeys;
+import org.eclipse.jetty.io.ssl.ResumptionStore;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class ResumptionKeysTest
+{
+    @Test
+    public void testKeysSharedViaStore()
+    {
+        ResumptionStore store = new ResumptionStore.InMemory();
+        ResumptionKeys keys1 = new ResumptionKeys(store, "test", 48, 60000);
+        ResumptionKeys keys2 = new ResumptionKeys(store, "test", 48, 60000);
+
+        byte[] key = keys1.getCurrentKey();
+        assertThat(key.length, is(48));
+        assertThat(keys2.getCurrentKey(), is(key));
+
+        // Keys with a different name are different.
+        ResumptionKeys other = new ResumptionKeys(store, "other", 48, 60000);
+        assertThat(other.getCurrentKey(), not(is(key)));
+    }
+
+    @Test
+    public void testRotation()
+    {
+        AtomicLong epoch = new AtomicLong(10);
+        ResumptionStore store = new ResumptionStore.InMemory();
+        ResumptionKeys keys = new ResumptionKeys(store, "test", 32, 60000)
+        {
+            @Override
+            protected long currentEpoch()
+            {
+                return epoch.get();
+            }
+        };
+
+        byte[] key10 = keys.getCurrentKey();
+        epoch.set(11);
+        byte[] key11 = keys.getCurrentKey();
+        assertThat(key11, not(is(key10)));
+        // The previous key is still available after the rotation.
+        List<byte[]> all = keys.getKeys();
+        assertThat(all.get(0), is(key11));
+        assertThat(all.get(1), is(key10));
+    }
+
+    @Test
+    public void testStoreFailureUsesLocalKey()
+    {
+        ResumptionKeys keys = new ResumptionKeys(new ResumptionStore()
+        {
+            @Override
+            public byte[] get(String key)
+            {
+                throw new IllegalStateException("explicitly_thrown_by_test");
+            }
+
+            @Override
+            public boolean putIfAbsent(String key, byte[] value, long ttl)
+            {
+                throw new IllegalStateException("explicitly_thrown_by_test");
+            }
+
+            @Override
+            public void remove(String key)
+            {
+            }
+        }, "test", 32, 60000);
+
+        byte[] key = keys.getCurrentKey();
+        assertThat(key, notNullValue());
+        assertThat(keys.getCurrentKey(), is(key));
+    }
+
+    @Test
+    public void testInMemoryExpiration() throws Exception
+    {
+        ResumptionStore store = new ResumptionStore.InMemory();
+        assertThat(store.putIfAbsent("key", new byte[]{1}, 100), is(true));
+        assertThat(store.putIfAbsent("key", new byte[]{2}, 100), is(false));
+        assertThat(store.get("key"), is(new byte[]{1}));
+
+        Thread.sleep(200);
+
+        assertThat(store.get("key"), nullValue());
+        assertThat(store.putIfAbsent("key", new byte[]{2}, 100), is(true));
+    }
+}
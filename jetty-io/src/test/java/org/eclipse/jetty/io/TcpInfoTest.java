@@ -0,0 +1,112 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.ByteOrder;
+import java.nio.channels.ServerSocketChannel;
+import java.nio.channels.SocketChannel;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.Map;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class TcpInfoTest
+{
+    public WorkDir workDir;
+
+    private static String hex(InetSocketAddress address)
+    {
+        int word = ByteBuffer.wrap(address.getAddress().getAddress()).order(ByteOrder.nativeOrder()).getInt();
+        return String.format("%08X:%04X", word, address.getPort());
+    }
+
+    @Test
+    public void testProcNetTcpProvider() throws Exception
+    {
+        try (ServerSocketChannel server = ServerSocketChannel.open();
+             SocketChannel client = SocketChannel.open())
+        {
+            server.bind(new InetSocketAddress(InetAddress.getLoopbackAddress(), 0));
+            client.connect(server.getLocalAddress());
+            try (SocketChannel accepted = server.accept())
+            {
+                Path dir = workDir.getEmptyPathDir();
+                String local = hex((InetSocketAddress)client.getLocalAddress());
+                String remote = hex((InetSocketAddress)client.getRemoteAddress());
+                Files.write(dir.resolve("tcp"), List.of(
+                    "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode",
+                    "   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   116        0 15538 1 0000000000000000 100 0 0 10 0",
+                    "   1: " + local + " " + remote + " 01 00000010:00000020 00:00000000 00000003  1000        0 12345 1 0000000000000000 20 4 30 10 -1",
+                    "   2: truncated"
+                ), StandardCharsets.US_ASCII);
+
+                TcpInfo.ProcNetTcpProvider provider = new TcpInfo.ProcNetTcpProvider(dir);
+                provider.init();
+                Map<SocketChannel, TcpInfo> samples = provider.sample(List.of(client, accepted));
+
+                // Only the client connection is in the file.
+                assertThat(samples.size(), is(1));
+                TcpInfo tcpInfo = samples.get(client);
+                assertThat(tcpInfo, notNullValue());
+                assertThat(tcpInfo.getRtt(), is(TcpInfo.UNKNOWN));
+                assertThat(tcpInfo.getRto(), is(200_000L));
+                assertThat(tcpInfo.getRetransmits(), is(3L));
+                assertThat(tcpInfo.getCongestionWindow(), is(10L));
+                assertThat(tcpInfo.getSendQueue(), is(16L));
+                assertThat(tcpInfo.getReceiveQueue(), is(32L));
+                assertThat(tcpInfo.getSendBufferSize(), greaterThan(0L));
+            }
+        }
+    }
+
+    @Test
+    public void testProcNetTcpProviderUnavailable()
+    {
+        TcpInfo.ProcNetTcpProvider provider = new TcpInfo.ProcNetTcpProvider(workDir.getEmptyPathDir());
+        assertThrows(UnsupportedOperationException.class, provider::init);
+    }
+
+    @Test
+    public void testSocketOptionsProvider() throws Exception
+    {
+        try (ServerSocketChannel server = ServerSocketChannel.open();
+             SocketChannel client = SocketChannel.open())
+        {
+            server.bind(new InetSocketAddress(InetAddress.getLoopbackAddress(), 0));
+            client.connect(server.getLocalAddress());
+            try (SocketChannel ignored = server.accept())
+            {
+                TcpInfo tcpInfo = new TcpInfo.SocketOptionsProvider().sample(List.of(client)).get(client);
+                assertThat(tcpInfo.getReceiveBufferSize(), greaterThan(0L));
+                assertThat(tcpInfo.getCongestionWindow(), is(TcpInfo.UNKNOWN));
+            }
+        }
+    }
+}
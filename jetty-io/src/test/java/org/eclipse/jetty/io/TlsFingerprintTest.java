@@ -0,0 +1,165 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.Arrays;
+import java.util.List;
+
+import org.eclipse.jetty.io.ssl.TlsFingerprint;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class TlsFingerprintTest
+{
+    @Test
+    public void testFingerprint()
+    {
+        TlsFingerprint fingerprint = TlsFingerprint.from(ByteBuffer.wrap(record(clientHello())));
+
+        assertThat(fingerprint.getLegacyVersion(), is(0x0303));
+        assertThat(fingerprint.getServerName(), is("example.com"));
+        assertThat(fingerprint.getApplicationProtocols(), is(List.of("h2", "http/1.1")));
+        // GREASE values are ignored.
+        assertThat(fingerprint.getJa3(), is("771,4865-4866-49195,0-10-11-13-16-43,29-23,0"));
+        assertThat(fingerprint.getJa3Hash(), is("11138d9933242c3a03b6aad35a296476"));
+        assertThat(fingerprint.getJa4(), is("t13d0306h2_5559582ccdc4_fb71836bce29"));
+    }
+
+    @Test
+    public void testFingerprintAcrossRecords()
+    {
+        byte[] clientHello = clientHello();
+        int split = clientHello.length / 2;
+        byte[] first = record(Arrays.copyOfRange(clientHello, 0, split));
+        byte[] second = record(Arrays.copyOfRange(clientHello, split, clientHello.length));
+        ByteBuffer records = ByteBuffer.allocate(first.length + second.length).put(first).put(second).flip();
+
+        // The first record alone is not enough.
+        assertThat(TlsFingerprint.from(ByteBuffer.wrap(first)), nullValue());
+        // A partial second record is not enough.
+        assertThat(TlsFingerprint.from(records.duplicate().limit(records.limit() - 1)), nullValue());
+
+        TlsFingerprint fingerprint = TlsFingerprint.from(records);
+        assertThat(fingerprint.getJa4(), is("t13d0306h2_5559582ccdc4_fb71836bce29"));
+        // The buffer position is not modified.
+        assertThat(records.position(), is(0));
+    }
+
+    @Test
+    public void testNotClientHello()
+    {
+        assertThrows(IllegalArgumentException.class, () -> TlsFingerprint.from(ByteBuffer.wrap("GET / HTTP/1.1\r\n".getBytes(StandardCharsets.US_ASCII))));
+
+        // A ServerHello message.
+        byte[] serverHello = clientHello();
+        serverHello[0] = 0x02;
+        assertThrows(IllegalArgumentException.class, () -> TlsFingerprint.from(ByteBuffer.wrap(record(serverHello))));
+
+        // A truncated ClientHello message.
+        byte[] truncated = clientHello();
+        truncated[3] = 0x10;
+        assertThrows(IllegalArgumentException.class, () -> TlsFingerprint.from(ByteBuffer.wrap(record(Arrays.copyOf(truncated, 4 + 0x10)))));
+    }
+
+    private static byte[] clientHello()
+    {
+        ByteArrayOutputStream body = new ByteArrayOutputStream();
+        writeShort(body, 0x0303);
+        body.writeBytes(new byte[32]);
+        // Empty session id.
+        body.write(0);
+        writeShorts(body, 0x0A0A, 0x1301, 0x1302, 0xC02B);
+        // Null compression.
+        body.write(1);
+        body.write(0);
+
+        ByteArrayOutputStream extensions = new ByteArrayOutputStream();
+        writeExtension(extensions, 0x0A0A, new byte[0]);
+        ByteArrayOutputStream serverName = new ByteArrayOutputStream();
+        byte[] host = "example.com".getBytes(StandardCharsets.US_ASCII);
+        writeShort(serverName, host.length + 3);
+        serverName.write(0);
+        writeShort(serverName, host.length);
+        serverName.writeBytes(host);
+        writeExtension(extensions, 0x0000, serverName.toByteArray());
+        ByteArrayOutputStream groups = new ByteArrayOutputStream();
+        writeShorts(groups, 0x1A1A, 0x001D, 0x0017);
+        writeExtension(extensions, 0x000A, groups.toByteArray());
+        writeExtension(extensions, 0x000B, new byte[]{1, 0});
+        ByteArrayOutputStream signatures = new ByteArrayOutputStream();
+        writeShorts(signatures, 0x0403, 0x0804);
+        writeExtension(extensions, 0x000D, signatures.toByteArray());
+        ByteArrayOutputStream alpn = new ByteArrayOutputStream();
+        writeShort(alpn, 12);
+        alpn.write(2);
+        alpn.writeBytes("h2".getBytes(StandardCharsets.US_ASCII));
+        alpn.write(8);
+        alpn.writeBytes("http/1.1".getBytes(StandardCharsets.US_ASCII));
+        writeExtension(extensions, 0x0010, alpn.toByteArray());
+        ByteArrayOutputStream versions = new ByteArrayOutputStream();
+        versions.write(6);
+        writeShort(versions, 0x3A3A);
+        writeShort(versions, 0x0304);
+        writeShort(versions, 0x0303);
+        writeExtension(extensions, 0x002B, versions.toByteArray());
+        writeShort(body, extensions.size());
+        body.writeBytes(extensions.toByteArray());
+
+        ByteArrayOutputStream message = new ByteArrayOutputStream();
+        message.write(0x01);
+        message.write(body.size() >> 16);
+        writeShort(message, body.size());
+        message.writeBytes(body.toByteArray());
+        return message.toByteArray();
+    }
+
+    private static byte[] record(byte[] fragment)
+    {
+        ByteArrayOutputStream record = new ByteArrayOutputStream();
+        record.write(0x16);
+        writeShort(record, 0x0301);
+        writeShort(record, fragment.length);
+        record.writeBytes(fragment);
+        return record.toByteArray();
+    }
+
+    private static void writeExtension(ByteArrayOutputStream output, int type, byte[] data)
+    {
+        writeShort(output, type);
+        writeShort(output, data.length);
+        output.writeBytes(data);
+    }
+
+    private static void writeShorts(ByteArrayOutputStream output, int... values)
+    {
+        writeShort(output, values.length * 2);
+        for (int value : values)
+        {
+            writeShort(output, value);
+        }
+    }
+
+    private static void writeShort(ByteArrayOutputStream output, int value)
+    {
+        output.write(value >> 8);
+        output.write(value);
+    }
+}
@@ -0,0 +1,355 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.io.dns;
+
+import java.io.ByteArrayOutputStream;
+import java.net.DatagramPacket;
+import java.net.DatagramSocket;
+import java.net.Inet4Address;
+import java.net.Inet6Address;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.UnknownHostException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.Promise;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class DnsResolverTest
+{
+    public WorkDir workDir;
+    private final Map<String, Response> responses = new ConcurrentHashMap<>();
+    private final AtomicInteger received = new AtomicInteger();
+    private DatagramSocket socket;
+    private DnsResolver resolver;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        socket = new DatagramSocket(0, InetAddress.getLoopbackAddress());
+        Thread thread = new Thread(this::serve);
+        thread.setDaemon(true);
+        thread.start();
+
+        resolver = new DnsResolver();
+        resolver.setNameServers(List.of(new InetSocketAddress(InetAddress.getLoopbackAddress(), socket.getLocalPort())));
+        resolver.setHostsFile(null);
+        resolver.setQueryTimeout(1000);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        resolver.stop();
+        socket.close();
+    }
+
+    @Test
+    public void testResolveIsCached() throws Exception
+    {
+        responses.put(DnsRecord.A + ":example.com", new Response(0, List.of(new Answer("example.com", DnsRecord.A, 60, address("127.0.0.2")))));
+        responses.put(DnsRecord.AAAA + ":example.com", new Response(0, List.of(new Answer("example.com", DnsRecord.AAAA, 60, address("::2")))));
+        resolver.start();
+
+        List<InetSocketAddress> addresses = resolve("example.com");
+        assertThat(addresses.size(), is(2));
+        assertThat(addresses.get(0).getAddress(), instanceOf(Inet4Address.class));
+        assertThat(addresses.get(0).getAddress().getHostAddress(), is("127.0.0.2"));
+        assertThat(addresses.get(0).getPort(), is(8080));
+        assertThat(addresses.get(1).getAddress(), instanceOf(Inet6Address.class));
+        assertThat(received.get(), is(2));
+
+        // Names are case insensitive.
+        addresses = resolve("EXAMPLE.com");
+        assertThat(addresses.size(), is(2));
+        assertThat(received.get(), is(2));
+        assertThat(resolver.getCacheHits(), is(2L));
+    }
+
+    @Test
+    public void testNegativeResultIsCached() throws Exception
+    {
+        Answer soa = new Answer("com", DnsRecord.SOA, 300, soa(60));
+        responses.put(DnsRecord.A + ":missing.com", new Response(DnsMessage.NXDOMAIN, List.of(), List.of(soa)));
+        responses.put(DnsRecord.AAAA + ":missing.com", new Response(DnsMessage.NXDOMAIN, List.of(), List.of(soa)));
+        resolver.start();
+
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> resolve("missing.com"));
+        assertThat(failure.getCause(), instanceOf(UnknownHostException.class));
+        assertThat(received.get(), is(2));
+
+        failure = assertThrows(ExecutionException.class, () -> resolve("missing.com"));
+        assertThat(failure.getCause(), instanceOf(UnknownHostException.class));
+        assertThat(received.get(), is(2));
+    }
+
+    @Test
+    public void testCNAMEIsFollowed() throws Exception
+    {
+        responses.put(DnsRecord.A + ":www.example.com", new Response(0, List.of(
+            new Answer("www.example.com", DnsRecord.CNAME, 60, name("target.example.com")),
+            new Answer("target.example.com", DnsRecord.A, 60, address("127.0.0.3")))));
+        resolver.start();
+
+        List<InetSocketAddress> addresses = resolve("www.example.com");
+        assertThat(addresses.size(), is(1));
+        assertThat(addresses.get(0).getAddress().getHostAddress(), is("127.0.0.3"));
+    }
+
+    @Test
+    public void testResolveSrv() throws Exception
+    {
+        responses.put(DnsRecord.SRV + ":_http._tcp.example.com", new Response(0, List.of(
+            new Answer("_http._tcp.example.com", DnsRecord.SRV, 60, srv(20, 10, 8080, "b.example.com")),
+            new Answer("_http._tcp.example.com", DnsRecord.SRV, 60, srv(10, 5, 8081, "a.example.com")))));
+        resolver.start();
+
+        CompletableFuture<List<DnsRecord>> result = new CompletableFuture<>();
+        resolver.resolveSrv("_http._tcp.example.com", Promise.from(result));
+        List<DnsRecord> records = result.get(5, TimeUnit.SECONDS);
+        assertThat(records.size(), is(2));
+        assertThat(records.get(0).getPriority(), is(10));
+        assertThat(records.get(0).getTarget(), is("a.example.com"));
+        assertThat(records.get(0).getPort(), is(8081));
+        assertThat(records.get(1).getTarget(), is("b.example.com"));
+    }
+
+    @Test
+    public void testResolveHttps() throws Exception
+    {
+        byte[] echConfigList = {0, 4, (byte)0xFE, 0x0D, 0, 0};
+        responses.put(DnsRecord.HTTPS + ":example.com", new Response(0, List.of(
+            new Answer("example.com", DnsRecord.HTTPS, 60, https(1, "", Map.of(DnsRecord.SVC_PARAM_ALPN, new byte[]{2, 'h', '2'}, DnsRecord.SVC_PARAM_ECH, echConfigList))))));
+        responses.put(DnsRecord.HTTPS + ":_8443._https.example.com", new Response(0, List.of(
+            new Answer("_8443._https.example.com", DnsRecord.HTTPS, 60, https(0, "alt.example.com", Map.of())))));
+        resolver.start();
+
+        CompletableFuture<List<DnsRecord>> result = new CompletableFuture<>();
+        resolver.resolveHttps("example.com", 443, Promise.from(result));
+        List<DnsRecord> records = result.get(5, TimeUnit.SECONDS);
+        assertThat(records.size(), is(1));
+        DnsRecord record = records.get(0);
+        assertThat(record.getType(), is(DnsRecord.HTTPS));
+        assertThat(record.getPriority(), is(1));
+        assertThat(record.getTarget(), is(""));
+        assertThat(record.getSvcParam(DnsRecord.SVC_PARAM_ALPN), is(new byte[]{2, 'h', '2'}));
+        assertThat(record.getECHConfigList(), is(echConfigList));
+
+        result = new CompletableFuture<>();
+        resolver.resolveHttps("example.com", 8443, Promise.from(result));
+        records = result.get(5, TimeUnit.SECONDS);
+        assertThat(records.get(0).getPriority(), is(0));
+        assertThat(records.get(0).getTarget(), is("alt.example.com"));
+        assertThat(records.get(0).getECHConfigList(), nullValue());
+    }
+
+    @Test
+    public void testHostsFileAndLiterals() throws Exception
+    {
+        Path hosts = workDir.getEmptyPathDir().resolve("hosts");
+        Files.writeString(hosts, "# Comment\n10.0.0.1  myhost.local myhost # Alias\n");
+        resolver.setHostsFile(hosts);
+        resolver.start();
+
+        List<InetSocketAddress> addresses = resolve("MyHost");
+        assertThat(addresses.size(), is(1));
+        assertThat(addresses.get(0).getAddress().getHostAddress(), is("10.0.0.1"));
+
+        addresses = resolve("[::1]");
+        assertThat(addresses.get(0).getAddress().isLoopbackAddress(), is(true));
+        addresses = resolve("127.0.0.1");
+        assertThat(addresses.get(0).getAddress().isLoopbackAddress(), is(true));
+
+        assertThat(received.get(), is(0));
+    }
+
+    @Test
+    public void testUnresponsiveNameServerTimesOut() throws Exception
+    {
+        try (DatagramSocket silent = new DatagramSocket(0, InetAddress.getLoopbackAddress()))
+        {
+            resolver.setNameServers(List.of(new InetSocketAddress(InetAddress.getLoopbackAddress(), silent.getLocalPort())));
+            resolver.setQueryTimeout(200);
+            resolver.setAttempts(1);
+            resolver.start();
+
+            ExecutionException failure = assertThrows(ExecutionException.class, () -> resolve("example.com"));
+            assertThat(failure.getCause(), instanceOf(UnknownHostException.class));
+            assertThat(resolver.getQueryFailures(), is(2L));
+        }
+    }
+
+    private List<InetSocketAddress> resolve(String host) throws Exception
+    {
+        CompletableFuture<List<InetSocketAddress>> result = new CompletableFuture<>();
+        resolver.resolve(host, 8080, Promise.from(result));
+        return result.get(5, TimeUnit.SECONDS);
+    }
+
+    private void serve()
+    {
+        byte[] buffer = new byte[512];
+        while (!socket.isClosed())
+        {
+            try
+            {
+                DatagramPacket packet = new DatagramPacket(buffer, buffer.length);
+                socket.receive(packet);
+                received.incrementAndGet();
+                DnsMessage query = DnsMessage.parse(ByteBuffer.wrap(buffer, 0, packet.getLength()));
+                Response response = responses.getOrDefault(query.getQuestionType() + ":" + query.getQuestionName(), new Response(0, List.of()));
+                byte[] bytes = response.encode(query);
+                socket.send(new DatagramPacket(bytes, bytes.length, packet.getSocketAddress()));
+            }
+            catch (Throwable x)
+            {
+                // Closed.
+            }
+        }
+    }
+
+    private static byte[] address(String literal) throws Exception
+    {
+        return InetAddress.getByName(literal).getAddress();
+    }
+
+    private static byte[] name(String name)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        for (String label : name.split("\\."))
+        {
+            output.write(label.length());
+            output.writeBytes(label.getBytes(StandardCharsets.US_ASCII));
+        }
+        output.write(0);
+        return output.toByteArray();
+    }
+
+    private static byte[] srv(int priority, int weight, int port, String target)
+    {
+        byte[] name = name(target);
+        return ByteBuffer.allocate(6 + name.length).putShort((short)priority).putShort((short)weight).putShort((short)port).put(name).array();
+    }
+
+    private static byte[] https(int priority, String target, Map<Integer, byte[]> params)
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        output.write(priority >>> 8);
+        output.write(priority);
+        output.writeBytes(target.isEmpty() ? new byte[1] : name(target));
+        // Service parameters must be in ascending key order.
+        params.keySet().stream().sorted().forEach(key ->
+        {
+            byte[] value = params.get(key);
+            output.write(key >>> 8);
+            output.write(key);
+            output.write(value.length >>> 8);
+            output.write(value.length);
+            output.writeBytes(value);
+        });
+        return output.toByteArray();
+    }
+
+    private static byte[] soa(int minimum)
+    {
+        byte[] mname = name("ns.com");
+        byte[] rname = name("admin.com");
+        return ByteBuffer.allocate(mname.length + rname.length + 20).put(mname).put(rname).position(mname.length + rname.length + 16).putInt(minimum).array();
+    }
+
+    private static class Answer
+    {
+        private final String name;
+        private final int type;
+        private final int ttl;
+        private final byte[] data;
+
+        private Answer(String name, int type, int ttl, byte[] data)
+        {
+            this.name = name;
+            this.type = type;
+            this.ttl = ttl;
+            this.data = data;
+        }
+
+        private void encode(ByteBuffer buffer, String question)
+        {
+            // Use a compression pointer to the question name.
+            if (name.equals(question))
+                buffer.putShort((short)0xC00C);
+            else
+                buffer.put(name(name));
+            buffer.putShort((short)type).putShort((short)1).putInt(ttl).putShort((short)data.length).put(data);
+        }
+    }
+
+    private static class Response
+    {
+        private final int code;
+        private final List<Answer> answers;
+        private final List<Answer> authorities;
+
+        private Response(int code, List<Answer> answers)
+        {
+            this(code, answers, List.of());
+        }
+
+        private Response(int code, List<Answer> answers, List<Answer> authorities)
+        {
+            this.code = code;
+            this.answers = answers;
+            this.authorities = authorities;
+        }
+
+        private byte[] encode(DnsMessage query)
+        {
+            ByteBuffer buffer = ByteBuffer.allocate(512);
+            buffer.putShort((short)query.getId())
+                .putShort((short)(0x8180 | code))
+                .putShort((short)1)
+                .putShort((short)answers.size())
+                .putShort((short)authorities.size())
+                .putShort((short)0);
+            buffer.put(name(query.getQuestionName())).putShort((short)query.getQuestionType()).putShort((short)1);
+            answers.forEach(answer -> answer.encode(buffer, query.getQuestionName()));
+            authorities.forEach(authority -> authority.encode(buffer, query.getQuestionName()));
+            buffer.flip();
+            byte[] bytes = new byte[buffer.remaining()];
+            buffer.get(bytes);
+            return bytes;
+        }
+    }
+}
@@ -16,7 +16,10 @@ package org.eclipse.jetty.maven.plugin;
 import java.io.File;
 import java.nio.file.Path;
 import java.nio.file.PathMatcher;
+import java.nio.file.Paths;
+import java.util.ArrayList;
 import java.util.Date;
+import java.util.List;
 import java.util.Set;
 
 import org.apache.maven.artifact.Artifact;
@@ -27,6 +30,7 @@ import org.apache.maven.plugins.annotations.LifecyclePhase;
 import org.apache.maven.plugins.annotations.Mojo;
 import org.apache.maven.plugins.annotations.Parameter;
 import org.apache.maven.plugins.annotations.ResolutionScope;
+import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.util.IncludeExcludeSet;
 import org.eclipse.jetty.util.Scanner;
 import org.eclipse.jetty.util.component.LifeCycle;
@@ -66,11 +70,40 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
     @Parameter(defaultValue = "-1", property = "jetty.scan", required = true)
     protected int scan;
 
+    /**
+     * Only valid for deploymentType=EMBED and scan &gt; 0.
+     * If true, changes that are limited to classes of the project or of
+     * sibling reactor modules do not cause a full redeploy: the webapp is
+     * only restarted with a fresh classloader, without reconfiguring it from
+     * the pom, re-resolving dependencies or reapplying overlays.
+     */
+    @Parameter(defaultValue = "false", property = "jetty.hotReload")
+    protected boolean hotReload;
+
+    /**
+     * Only valid for deploymentType=EMBED.
+     * If true, a {@link LiveReloadContext} is deployed, so that browser pages
+     * that include the {@code /__livereload/livereload.js} script are reloaded
+     * after every redeploy of the webapp.
+     */
+    @Parameter(defaultValue = "false", property = "jetty.liveReload")
+    protected boolean liveReload;
+
     /**
      * Scanner to check for files changes to cause redeploy
      */
     protected Scanner scanner;
 
+    /**
+     * Directories of classes that can be hot reloaded
+     */
+    protected List<Path> hotReloadDirectories = new ArrayList<>();
+
+    /**
+     * Context to trigger the reload of browser pages
+     */
+    protected LiveReloadContext liveReloadContext;
+
     /**
      * Only one of the following will be used, depending the mode
      * the mojo is started in: EMBED, FORK, EXTERNAL
@@ -94,6 +127,16 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
             embedder = newJettyEmbedder();
             embedder.setExitVm(true);
             embedder.setStopAtShutdown(true);
+            if (liveReload)
+            {
+                liveReloadContext = new LiveReloadContext();
+                List<ContextHandler> handlers = new ArrayList<>();
+                if (embedder.getContextHandlers() != null)
+                    handlers.addAll(embedder.getContextHandlers());
+                handlers.add(liveReloadContext);
+                embedder.setContextHandlers(handlers);
+                getLog().info("Live reload enabled, include <script src=\"" + LiveReloadContext.DEFAULT_CONTEXT_PATH + "/livereload.js\"></script> in pages");
+            }
             embedder.start();
             startScanner();
             embedder.join();
@@ -109,6 +152,7 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
     {
         try
         {
+            warnEmbedOnly();
             forker = newJettyForker();
             forker.setWaitForChild(true); //we run at the command line, echo child output and wait for it
             forker.setScan(true); //have the forked child notice changes to the webapp
@@ -127,6 +171,7 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
     {
         try
         {
+            warnEmbedOnly();
             homeForker = newJettyHomeForker();
             homeForker.setWaitForChild(true); //we always run at the command line, echo child output and wait for it
             //TODO is it ok to start the scanner before we start jetty?
@@ -161,7 +206,10 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
                 {
                     try
                     {
-                        restartWebApp(changes.contains(project.getFile().getCanonicalPath()));
+                        if (isHotReloadable(changes))
+                            hotReloadWebApp(changes);
+                        else
+                            restartWebApp(changes.contains(project.getFile().getCanonicalPath()));
                     }
                     catch (Exception e)
                     {
@@ -202,6 +250,14 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
         }
     }
 
+    private void warnEmbedOnly()
+    {
+        if (hotReload)
+            getLog().warn("Hot reload is only supported for deploymentType=EMBED, using full redeploy");
+        if (liveReload)
+            getLog().warn("Live reload is only supported for deploymentType=EMBED");
+    }
+
     protected void configureScanner()
         throws MojoExecutionException
     {
@@ -279,9 +335,11 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
             }
         }
 
+        hotReloadDirectories.clear();
         if (webApp.getClasses() != null && webApp.getClasses().exists())
         {
             Path p = webApp.getClasses().toPath();
+            hotReloadDirectories.add(p);
             IncludeExcludeSet<PathMatcher, Path> includeExcludes = scanner.addDirectory(p);
             if (scanClassesPattern != null)
             {
@@ -306,13 +364,71 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
             for (File f : webApp.getWebInfLib())
             {
                 if (f.isDirectory())
+                {
+                    //a directory is the output of a sibling module in the reactor
                     scanner.addDirectory(f.toPath());
+                    hotReloadDirectories.add(f.toPath());
+                }
                 else
                     scanner.addFile(f.toPath());
             }
         }
     }
 
+    /**
+     * Check if the changes can be applied by restarting the webapp
+     * with a fresh classloader, without reconfiguring it.
+     *
+     * @param changes the changed files
+     * @return true if all the changes are classes in the hot reload directories
+     */
+    protected boolean isHotReloadable(Set<String> changes)
+    {
+        if (!hotReload || deployMode != DeploymentMode.EMBED || changes.isEmpty())
+            return false;
+
+        for (String change : changes)
+        {
+            if (!change.endsWith(".class"))
+                return false;
+            Path path = Paths.get(change);
+            if (hotReloadDirectories.stream().noneMatch(path::startsWith))
+                return false;
+        }
+        return true;
+    }
+
+    /**
+     * Restart the webapp so that changed classes are loaded by a new
+     * classloader, skipping the reconfiguration of the webapp.
+     *
+     * @param changes the changed classes
+     * @throws Exception if the webapp cannot be restarted
+     */
+    public void hotReloadWebApp(Set<String> changes) throws Exception
+    {
+        getLog().info("Hot reloading " + changes.size() + " changed classes in " + webApp);
+        if (getLog().isDebugEnabled())
+        {
+            for (String change : changes)
+            {
+                getLog().debug("Changed class " + change);
+            }
+        }
+        if (scanner != null)
+            scanner.stop();
+
+        MavenWebAppContext context = embedder.getWebApp();
+        context.stop();
+        context.start();
+
+        if (scanner != null)
+            scanner.start();
+        if (liveReloadContext != null)
+            liveReloadContext.reload();
+        getLog().info("Hot reload completed at " + new Date().toString());
+    }
+
     /**
      * Stop an executing webapp and restart it after optionally
      * reconfiguring it.
@@ -355,6 +471,8 @@ public class JettyRunMojo extends AbstractUnassembledWebAppMojo
                 embedder.redeployWebApp();
                 if (scanner != null)
                     scanner.start();
+                if (liveReloadContext != null)
+                    liveReloadContext.reload();
                 getLog().info("Restart completed at " + new Date().toString());
 
                 break;
@@ -0,0 +1,139 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.maven.plugin;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArraySet;
+import javax.servlet.http.HttpServlet;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.servlet.ServletHolder;
+import org.eclipse.jetty.websocket.api.Session;
+import org.eclipse.jetty.websocket.api.WebSocketAdapter;
+import org.eclipse.jetty.websocket.api.WriteCallback;
+import org.eclipse.jetty.websocket.server.config.JettyWebSocketServletContainerInitializer;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * LiveReloadContext
+ *
+ * A context that triggers a reload of the browser pages of the webapp
+ * after the webapp has been redeployed.
+ * <p>
+ * Pages opt in by including the script served by this context, for example
+ * {@code <script src="/__livereload/livereload.js"></script>}. The script opens
+ * a websocket to this context, and reloads the page when {@link #reload()} is called.
+ * If the websocket is disconnected, for example because the server is restarting,
+ * the script reconnects and reloads the page once the server is available again.
+ */
+public class LiveReloadContext extends ServletContextHandler
+{
+    public static final String DEFAULT_CONTEXT_PATH = "/__livereload";
+    private static final Logger LOG = LoggerFactory.getLogger(LiveReloadContext.class);
+    private static final String RELOAD = "reload";
+
+    private final Set<Session> sessions = new CopyOnWriteArraySet<>();
+
+    public LiveReloadContext()
+    {
+        this(DEFAULT_CONTEXT_PATH);
+    }
+
+    public LiveReloadContext(String contextPath)
+    {
+        super(contextPath);
+        setDisplayName("livereload");
+        addServlet(new ServletHolder(new ScriptServlet()), "/livereload.js");
+        JettyWebSocketServletContainerInitializer.configure(this, (servletContext, container) ->
+            container.addMapping("/ws", (request, response) -> new ReloadSocket()));
+    }
+
+    /**
+     * @return the number of browser pages currently connected
+     */
+    public int getConnectedPages()
+    {
+        return sessions.size();
+    }
+
+    /**
+     * Tell all connected browser pages to reload.
+     */
+    public void reload()
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Reloading {} pages", sessions.size());
+        for (Session session : sessions)
+        {
+            session.getRemote().sendString(RELOAD, WriteCallback.NOOP);
+        }
+    }
+
+    private String script()
+    {
+        String path = getContextPath() + "/ws";
+        return "(function() {\n" +
+            "  var connected = false;\n" +
+            "  function connect() {\n" +
+            "    var scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';\n" +
+            "    var ws = new WebSocket(scheme + location.host + '" + path + "');\n" +
+            "    ws.onopen = function() { if (connected) location.reload(); connected = true; };\n" +
+            "    ws.onmessage = function(e) { if (e.data === '" + RELOAD + "') location.reload(); };\n" +
+            "    ws.onclose = function() { setTimeout(connect, 1000); };\n" +
+            "  }\n" +
+            "  connect();\n" +
+            "})();\n";
+    }
+
+    private class ScriptServlet extends HttpServlet
+    {
+        @Override
+        protected void doGet(HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            response.setContentType("application/javascript");
+            response.setCharacterEncoding(StandardCharsets.UTF_8.name());
+            response.setHeader("Cache-Control", "no-store");
+            response.getWriter().write(script());
+        }
+    }
+
+    private class ReloadSocket extends WebSocketAdapter
+    {
+        @Override
+        public void onWebSocketConnect(Session session)
+        {
+            super.onWebSocketConnect(session);
+            sessions.add(session);
+        }
+
+        @Override
+        public void onWebSocketClose(int statusCode, String reason)
+        {
+            sessions.remove(getSession());
+            super.onWebSocketClose(statusCode, reason);
+        }
+
+        @Override
+        public void onWebSocketError(Throwable cause)
+        {
+            sessions.remove(getSession());
+            super.onWebSocketError(cause);
+        }
+    }
+}
@@ -0,0 +1,59 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.maven.plugin;
+
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class TestLiveReloadContext
+{
+    private Server server;
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (server != null)
+            server.stop();
+    }
+
+    @Test
+    public void testScript() throws Exception
+    {
+        server = new Server();
+        LocalConnector connector = new LocalConnector(server);
+        server.addConnector(connector);
+        LiveReloadContext context = new LiveReloadContext();
+        server.setHandler(context);
+        server.start();
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(
+            "GET " + LiveReloadContext.DEFAULT_CONTEXT_PATH + "/livereload.js HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Connection: close\r\n" +
+            "\r\n"));
+        assertEquals(200, response.getStatus());
+        assertTrue(response.get("Content-Type").startsWith("application/javascript"));
+        assertTrue(response.getContent().contains("'" + LiveReloadContext.DEFAULT_CONTEXT_PATH + "/ws'"));
+
+        // No pages are connected, so reloading is a no-op.
+        assertEquals(0, context.getConnectedPages());
+        context.reload();
+    }
+}
@@ -0,0 +1,15 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Adds the HTTP/2 sessions and streams metrics to the metrics module.
+
+[tags]
+server
+http2
+
+[depend]
+metrics
+http2
+
+[xml]
+etc/jetty-metrics-http2.xml
@@ -0,0 +1,24 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Serves the metrics of the server (thread pool, connectors, sessions
+and buffer pools) at /metrics, in the OpenMetrics or Prometheus text
+format, to be scraped by Prometheus or compatible systems.
+Enable the stats module for the connector traffic and request metrics,
+and the usage module for the usage metrics.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-metrics.xml
+
+[lib]
+lib/jetty-metrics-${jetty.version}.jar
+
+[ini-template]
+## The path of the metrics.
+# jetty.metrics.path=/metrics
@@ -0,0 +1,25 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+module org.eclipse.jetty.metrics
+{
+    requires transitive org.eclipse.jetty.server;
+    requires org.slf4j;
+
+    // Only required if using HTTP2Metrics.
+    requires static org.eclipse.jetty.http2.common;
+
+    exports org.eclipse.jetty.metrics;
+
+    uses org.eclipse.jetty.metrics.MetricsBridge;
+}
@@ -0,0 +1,73 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Collections;
+import java.util.IdentityHashMap;
+import java.util.Objects;
+import java.util.Set;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.io.AbstractByteBufferPool;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link MetricsCollector} for the memory retained by the {@link ByteBufferPool}s
+ * of a {@link Server} and of its {@link Connector}s.</p>
+ */
+@ManagedObject("ByteBufferPool metrics")
+public class ByteBufferPoolMetrics implements MetricsCollector
+{
+    private final Server server;
+
+    public ByteBufferPoolMetrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        // The pool is typically shared by the server and all the connectors.
+        Set<ByteBufferPool> pools = Collections.newSetFromMap(new IdentityHashMap<>());
+        pools.addAll(server.getBeans(ByteBufferPool.class));
+        for (Connector connector : server.getConnectors())
+        {
+            if (connector.getByteBufferPool() != null)
+                pools.add(connector.getByteBufferPool());
+        }
+
+        for (ByteBufferPool pool : pools)
+        {
+            if (!(pool instanceof AbstractByteBufferPool))
+                continue;
+            AbstractByteBufferPool bufferPool = (AbstractByteBufferPool)pool;
+            String name = String.format("%s@%x", pool.getClass().getSimpleName(), pool.hashCode());
+            metrics.accept(Metric.gauge("jetty_bytebufferpool_memory_bytes", "The memory retained by the pool", bufferPool.getDirectMemory(), "pool", name, "type", "direct"));
+            metrics.accept(Metric.gauge("jetty_bytebufferpool_memory_bytes", "The memory retained by the pool", bufferPool.getHeapMemory(), "pool", name, "type", "heap"));
+            metrics.accept(Metric.gauge("jetty_bytebufferpool_memory_max_bytes", "The max memory retained by the pool", bufferPool.getMaxDirectMemory(), "pool", name, "type", "direct"));
+            metrics.accept(Metric.gauge("jetty_bytebufferpool_memory_max_bytes", "The max memory retained by the pool", bufferPool.getMaxHeapMemory(), "pool", name, "type", "heap"));
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
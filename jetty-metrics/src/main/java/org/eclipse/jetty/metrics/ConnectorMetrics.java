@@ -0,0 +1,108 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.io.ConnectionStatistics;
+import org.eclipse.jetty.io.SocketTelemetry;
+import org.eclipse.jetty.io.TcpInfo;
+import org.eclipse.jetty.server.AbstractConnector;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.NetworkConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link MetricsCollector} for the connections of the {@link Connector}s of a {@link Server}.</p>
+ * <p>The traffic metrics are only available for the connectors that have a
+ * {@link ConnectionStatistics} bean, and the TCP metrics for the connectors
+ * that have a {@link SocketTelemetry} bean, if the platform provides them.</p>
+ */
+@ManagedObject("Connector metrics")
+public class ConnectorMetrics implements MetricsCollector
+{
+    private final Server server;
+
+    public ConnectorMetrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        for (Connector connector : server.getConnectors())
+        {
+            String name = nameOf(connector);
+            metrics.accept(Metric.gauge("jetty_connector_connections", "The number of open connections", connector.getConnectedEndPoints().size(), "connector", name));
+            if (connector instanceof AbstractConnector)
+            {
+                metrics.accept(Metric.gauge("jetty_connector_accepting", "Whether the connector is accepting connections", ((AbstractConnector)connector).isAccepting() ? 1 : 0, "connector", name));
+            }
+
+            SocketTelemetry telemetry = connector.getBean(SocketTelemetry.class);
+            if (telemetry != null)
+                collectTcp(telemetry, name, metrics);
+
+            ConnectionStatistics statistics = connector.getBean(ConnectionStatistics.class);
+            if (statistics == null)
+                continue;
+            metrics.accept(Metric.gauge("jetty_connector_connections_max", "The max number of concurrent connections", statistics.getConnectionsMax(), "connector", name));
+            metrics.accept(Metric.counter("jetty_connector_connections_opened", "The number of connections opened", statistics.getConnectionsTotal(), "connector", name));
+            metrics.accept(Metric.counter("jetty_connector_received_bytes", "The number of bytes received", statistics.getReceivedBytes(), "connector", name));
+            metrics.accept(Metric.counter("jetty_connector_sent_bytes", "The number of bytes sent", statistics.getSentBytes(), "connector", name));
+            metrics.accept(Metric.counter("jetty_connector_received_messages", "The number of messages received", statistics.getReceivedMessages(), "connector", name));
+            metrics.accept(Metric.counter("jetty_connector_sent_messages", "The number of messages sent", statistics.getSentMessages(), "connector", name));
+        }
+    }
+
+    private void collectTcp(SocketTelemetry telemetry, String name, Consumer<Metric> metrics)
+    {
+        long rtt = telemetry.getRttAverage();
+        if (rtt != TcpInfo.UNKNOWN)
+            metrics.accept(Metric.gauge("jetty_connector_tcp_rtt_seconds", "The average smoothed round trip time of the connections", rtt / 1_000_000D, "connector", name));
+        long rto = telemetry.getRtoAverage();
+        if (rto != TcpInfo.UNKNOWN)
+            metrics.accept(Metric.gauge("jetty_connector_tcp_rto_seconds", "The average retransmission timeout of the connections", rto / 1_000_000D, "connector", name));
+        long congestionWindow = telemetry.getCongestionWindowAverage();
+        if (congestionWindow != TcpInfo.UNKNOWN)
+            metrics.accept(Metric.gauge("jetty_connector_tcp_congestion_window", "The average congestion window of the connections, in segments", congestionWindow, "connector", name));
+        long retransmits = telemetry.getRetransmits();
+        if (retransmits != TcpInfo.UNKNOWN)
+            metrics.accept(Metric.gauge("jetty_connector_tcp_retransmits", "The retransmissions of the connections", retransmits, "connector", name));
+    }
+
+    /**
+     * @param connector the connector
+     * @return the connector name, or a name made of its protocols and port if it has no name
+     */
+    static String nameOf(Connector connector)
+    {
+        if (connector.getName() != null)
+            return connector.getName();
+        String name = String.join("|", connector.getProtocols());
+        if (connector instanceof NetworkConnector)
+            name += "@" + ((NetworkConnector)connector).getPort();
+        return name;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
@@ -0,0 +1,73 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.http2.HTTP2Connection;
+import org.eclipse.jetty.http2.ISession;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link MetricsCollector} for the HTTP/2 sessions of the {@link Connector}s of a {@link Server}.</p>
+ * <p>This collector requires the {@code http2-common} artifact.</p>
+ */
+@ManagedObject("HTTP/2 metrics")
+public class HTTP2Metrics implements MetricsCollector
+{
+    private final Server server;
+
+    public HTTP2Metrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        for (Connector connector : server.getConnectors())
+        {
+            int sessions = 0;
+            int streams = 0;
+            for (EndPoint endPoint : connector.getConnectedEndPoints())
+            {
+                Connection connection = endPoint.getConnection();
+                if (connection instanceof HTTP2Connection)
+                {
+                    ISession session = ((HTTP2Connection)connection).getSession();
+                    ++sessions;
+                    streams += session.getStreams().size();
+                }
+            }
+            // Skip connectors that do not speak HTTP/2.
+            if (sessions == 0 && connector.getProtocols().stream().noneMatch(protocol -> protocol.startsWith("h2")))
+                continue;
+            String name = ConnectorMetrics.nameOf(connector);
+            metrics.accept(Metric.gauge("jetty_http2_sessions", "The number of HTTP/2 sessions", sessions, "connector", name));
+            metrics.accept(Metric.gauge("jetty_http2_streams", "The number of HTTP/2 streams", streams, "connector", name));
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
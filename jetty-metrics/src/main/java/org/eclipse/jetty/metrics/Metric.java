@@ -0,0 +1,156 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.regex.Pattern;
+
+/**
+ * <p>A sample of a metric, with a name, a type, a help text, a set of labels and a value.</p>
+ * <p>Samples with the same name belong to the same metric family, and must have
+ * the same type and help text, but different labels.</p>
+ */
+public class Metric
+{
+    private static final Pattern NAME_PATTERN = Pattern.compile("[a-zA-Z_:][a-zA-Z0-9_:]*");
+    private static final Pattern LABEL_PATTERN = Pattern.compile("[a-zA-Z_][a-zA-Z0-9_]*");
+
+    /**
+     * <p>The metric types.</p>
+     */
+    public enum Type
+    {
+        /**
+         * <p>A value that can go up and down, such as the number of threads.</p>
+         */
+        GAUGE,
+        /**
+         * <p>A value that only goes up, such as the number of bytes received.</p>
+         */
+        COUNTER
+    }
+
+    /**
+     * <p>Creates a gauge sample.</p>
+     *
+     * @param name the metric name
+     * @param help the metric help text
+     * @param value the sample value
+     * @param labels pairs of label names and values
+     * @return a new gauge sample
+     */
+    public static Metric gauge(String name, String help, double value, String... labels)
+    {
+        return new Metric(name, Type.GAUGE, help, toMap(labels), value);
+    }
+
+    /**
+     * <p>Creates a counter sample.</p>
+     * <p>The name of the counter must not have the {@code _total} suffix,
+     * that is added when the sample is written.</p>
+     *
+     * @param name the metric name
+     * @param help the metric help text
+     * @param value the sample value
+     * @param labels pairs of label names and values
+     * @return a new counter sample
+     */
+    public static Metric counter(String name, String help, double value, String... labels)
+    {
+        return new Metric(name, Type.COUNTER, help, toMap(labels), value);
+    }
+
+    private static Map<String, String> toMap(String... labels)
+    {
+        if (labels.length % 2 != 0)
+            throw new IllegalArgumentException("Labels must be name/value pairs");
+        Map<String, String> result = new LinkedHashMap<>();
+        for (int i = 0; i < labels.length; i += 2)
+        {
+            result.put(labels[i], labels[i + 1]);
+        }
+        return result;
+    }
+
+    private final String name;
+    private final Type type;
+    private final String help;
+    private final Map<String, String> labels;
+    private final double value;
+
+    public Metric(String name, Type type, String help, Map<String, String> labels, double value)
+    {
+        if (!NAME_PATTERN.matcher(name).matches())
+            throw new IllegalArgumentException("Invalid metric name " + name);
+        for (String label : labels.keySet())
+        {
+            if (!LABEL_PATTERN.matcher(label).matches())
+                throw new IllegalArgumentException("Invalid label name " + label);
+        }
+        this.name = name;
+        this.type = Objects.requireNonNull(type);
+        this.help = help == null ? "" : help;
+        this.labels = Collections.unmodifiableMap(new LinkedHashMap<>(labels));
+        this.value = value;
+    }
+
+    /**
+     * @return the metric name
+     */
+    public String getName()
+    {
+        return name;
+    }
+
+    /**
+     * @return the metric type
+     */
+    public Type getType()
+    {
+        return type;
+    }
+
+    /**
+     * @return the metric help text
+     */
+    public String getHelp()
+    {
+        return help;
+    }
+
+    /**
+     * @return the labels of this sample
+     */
+    public Map<String, String> getLabels()
+    {
+        return labels;
+    }
+
+    /**
+     * @return the value of this sample
+     */
+    public double getValue()
+    {
+        return value;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s%s=%s]", getClass().getSimpleName(), hashCode(), name, labels, value);
+    }
+}
@@ -0,0 +1,43 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+/**
+ * <p>A service provider interface to bridge the metrics of a {@link MetricsRegistry}
+ * into another metrics library, such as Micrometer, without this module depending
+ * on that library.</p>
+ * <p>Implementations are discovered via {@link java.util.ServiceLoader} when the
+ * {@link MetricsRegistry} is started, or may be added explicitly via
+ * {@link MetricsRegistry#addBridge(MetricsBridge)}.
+ * Typical implementations register in the other library a meter that polls
+ * {@link MetricsRegistry#collect()}.</p>
+ */
+public interface MetricsBridge
+{
+    /**
+     * <p>Callback method invoked when the given registry is started.</p>
+     *
+     * @param registry the registry to bridge
+     */
+    void bind(MetricsRegistry registry);
+
+    /**
+     * <p>Callback method invoked when the given registry is stopped.</p>
+     *
+     * @param registry the registry that was bridged
+     */
+    default void unbind(MetricsRegistry registry)
+    {
+    }
+}
@@ -0,0 +1,39 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.function.Consumer;
+
+/**
+ * <p>Collects the current values of a set of metrics.</p>
+ * <p>Collectors are invoked every time the metrics are scraped, so that
+ * the metrics reflect the components that exist at the time of the scrape,
+ * for example connectors or contexts that have been added after the server
+ * started.</p>
+ * <p>Collectors may be {@link MetricsRegistry#addCollector(MetricsCollector)
+ * added} to a {@link MetricsRegistry} or added as beans to the
+ * {@link org.eclipse.jetty.server.Server}.</p>
+ */
+@FunctionalInterface
+public interface MetricsCollector
+{
+    /**
+     * <p>Collects the current values of the metrics.</p>
+     * <p>This method must be fast and must not block, as it is
+     * invoked for every scrape.</p>
+     *
+     * @param metrics the consumer of the metric samples
+     */
+    void collect(Consumer<Metric> metrics);
+}
@@ -0,0 +1,136 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Objects;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+
+/**
+ * <p>A {@link HandlerWrapper} that serves the metrics of a {@link MetricsRegistry},
+ * by default at {@code /metrics}, passing all other requests to the wrapped handler.</p>
+ * <p>The metrics are collected at every request, so that scrapers such as Prometheus
+ * pull the current values, and are written in the
+ * <a href="https://openmetrics.io/">OpenMetrics</a> text format if the request
+ * accepts it, or in the Prometheus text format otherwise.</p>
+ * <p>The {@link MetricsCollector}s are those added to the {@link #getMetricsRegistry() registry}
+ * along with those added as beans to the {@link Server}.</p>
+ */
+@ManagedObject("Metrics handler")
+public class MetricsHandler extends HandlerWrapper
+{
+    private static final String OPENMETRICS_CONTENT_TYPE = "application/openmetrics-text; version=1.0.0; charset=utf-8";
+    private static final String PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8";
+
+    private final MetricsRegistry registry;
+    private String path = "/metrics";
+
+    public MetricsHandler()
+    {
+        this(new MetricsRegistry());
+    }
+
+    public MetricsHandler(MetricsRegistry registry)
+    {
+        this.registry = Objects.requireNonNull(registry);
+        addBean(registry);
+    }
+
+    /**
+     * @return the registry of the metrics served by this handler
+     */
+    public MetricsRegistry getMetricsRegistry()
+    {
+        return registry;
+    }
+
+    /**
+     * <p>Convenience method equivalent to {@code getMetricsRegistry().addCollector(collector)}.</p>
+     *
+     * @param collector the collector to add
+     */
+    public void addCollector(MetricsCollector collector)
+    {
+        registry.addCollector(collector);
+    }
+
+    @ManagedAttribute("The path of the metrics")
+    public String getPath()
+    {
+        return path;
+    }
+
+    public void setPath(String path)
+    {
+        this.path = path;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (registry.getServer() == null)
+            registry.setServer(getServer());
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (!target.equals(path))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setHandled(true);
+        String method = request.getMethod();
+        boolean head = HttpMethod.HEAD.is(method);
+        if (!head && !HttpMethod.GET.is(method))
+        {
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+
+        String accept = request.getHeader(HttpHeader.ACCEPT.asString());
+        boolean openMetrics = accept != null && accept.contains("application/openmetrics-text");
+        StringBuilder builder = new StringBuilder();
+        registry.write(builder, openMetrics);
+        byte[] content = builder.toString().getBytes(StandardCharsets.UTF_8);
+
+        response.setStatus(HttpStatus.OK_200);
+        response.setContentType(openMetrics ? OPENMETRICS_CONTENT_TYPE : PROMETHEUS_CONTENT_TYPE);
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-store");
+        response.setContentLength(content.length);
+        if (!head)
+            response.getOutputStream().write(content);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[path=%s,%s]", getClass().getSimpleName(), hashCode(), path, registry);
+    }
+}
@@ -0,0 +1,292 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.ServiceLoader;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.TypeUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A registry of {@link MetricsCollector}s, that collects the metrics
+ * on demand and writes them in the
+ * <a href="https://openmetrics.io/">OpenMetrics</a> or in the
+ * Prometheus text format.</p>
+ * <p>The collectors are those {@link #addCollector(MetricsCollector) added}
+ * to this registry, followed by those added as beans to the {@link Server},
+ * if one is {@link #setServer(Server) set}.</p>
+ * <p>When started, this registry binds the {@link MetricsBridge}s discovered
+ * via {@link ServiceLoader}, along with those explicitly
+ * {@link #addBridge(MetricsBridge) added}.</p>
+ */
+@ManagedObject("Metrics registry")
+public class MetricsRegistry extends ContainerLifeCycle
+{
+    private static final Logger LOG = LoggerFactory.getLogger(MetricsRegistry.class);
+
+    private final List<MetricsCollector> collectors = new CopyOnWriteArrayList<>();
+    private final List<MetricsBridge> bridges = new CopyOnWriteArrayList<>();
+    private final List<MetricsBridge> boundBridges = new CopyOnWriteArrayList<>();
+    private Server server;
+
+    /**
+     * @return the server whose bean collectors are used, or null
+     */
+    public Server getServer()
+    {
+        return server;
+    }
+
+    /**
+     * @param server the server whose bean collectors are used
+     */
+    public void setServer(Server server)
+    {
+        this.server = server;
+    }
+
+    /**
+     * @param collector the collector to add
+     */
+    public void addCollector(MetricsCollector collector)
+    {
+        collectors.add(Objects.requireNonNull(collector));
+        addBean(collector);
+    }
+
+    /**
+     * @param collector the collector to remove
+     * @return whether the collector was removed
+     */
+    public boolean removeCollector(MetricsCollector collector)
+    {
+        removeBean(collector);
+        return collectors.remove(collector);
+    }
+
+    /**
+     * @return the collectors added to this registry, followed by those added as beans to the server
+     */
+    public List<MetricsCollector> getCollectors()
+    {
+        Set<MetricsCollector> result = new LinkedHashSet<>(collectors);
+        Server server = getServer();
+        if (server != null)
+            result.addAll(server.getBeans(MetricsCollector.class));
+        return new ArrayList<>(result);
+    }
+
+    /**
+     * @param bridge the bridge to bind when this registry is started
+     */
+    public void addBridge(MetricsBridge bridge)
+    {
+        bridges.add(Objects.requireNonNull(bridge));
+        addBean(bridge);
+    }
+
+    /**
+     * @param bridge the bridge to remove
+     * @return whether the bridge was removed
+     */
+    public boolean removeBridge(MetricsBridge bridge)
+    {
+        removeBean(bridge);
+        return bridges.remove(bridge);
+    }
+
+    @ManagedAttribute("The number of collectors")
+    public int getCollectorCount()
+    {
+        return getCollectors().size();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        super.doStart();
+        List<MetricsBridge> toBind = new ArrayList<>(bridges);
+        TypeUtil.serviceStream(ServiceLoader.load(MetricsBridge.class)).forEach(toBind::add);
+        for (MetricsBridge bridge : toBind)
+        {
+            try
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Binding {} to {}", bridge, this);
+                bridge.bind(this);
+                boundBridges.add(bridge);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Could not bind {}", bridge, x);
+            }
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        for (MetricsBridge bridge : boundBridges)
+        {
+            try
+            {
+                bridge.unbind(this);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("failure unbinding bridge {}", bridge, x);
+            }
+        }
+        boundBridges.clear();
+        super.doStop();
+    }
+
+    /**
+     * <p>Collects the current values of the metrics from all the collectors.</p>
+     * <p>A collector that throws is skipped, so that it does not
+     * prevent the other metrics from being collected.</p>
+     *
+     * @return the metric samples
+     */
+    public List<Metric> collect()
+    {
+        List<Metric> result = new ArrayList<>();
+        for (MetricsCollector collector : getCollectors())
+        {
+            List<Metric> metrics = new ArrayList<>();
+            try
+            {
+                collector.collect(metrics::add);
+                result.addAll(metrics);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Could not collect metrics from {}", collector, x);
+            }
+        }
+        return result;
+    }
+
+    /**
+     * <p>Collects the metrics and writes them in the given format.</p>
+     *
+     * @param out where to write the metrics
+     * @param openMetrics whether to use the OpenMetrics format rather than the Prometheus text format
+     * @throws IOException if the metrics cannot be written
+     */
+    public void write(Appendable out, boolean openMetrics) throws IOException
+    {
+        Map<String, List<Metric>> families = new LinkedHashMap<>();
+        for (Metric metric : collect())
+        {
+            families.computeIfAbsent(metric.getName(), k -> new ArrayList<>()).add(metric);
+        }
+
+        for (List<Metric> family : families.values())
+        {
+            Metric first = family.get(0);
+            // OpenMetrics names counter families without the suffix of their samples.
+            String sampleName = first.getType() == Metric.Type.COUNTER ? first.getName() + "_total" : first.getName();
+            String familyName = openMetrics ? first.getName() : sampleName;
+            out.append("# TYPE ").append(familyName).append(' ').append(first.getType().name().toLowerCase(Locale.ENGLISH)).append('\n');
+            if (!first.getHelp().isEmpty())
+                out.append("# HELP ").append(familyName).append(' ').append(escape(first.getHelp(), openMetrics)).append('\n');
+            for (Metric metric : family)
+            {
+                if (metric.getType() != first.getType())
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Skipping {}, type mismatch with {}", metric, first);
+                    continue;
+                }
+                out.append(sampleName);
+                Map<String, String> labels = metric.getLabels();
+                if (!labels.isEmpty())
+                {
+                    out.append('{');
+                    boolean comma = false;
+                    for (Map.Entry<String, String> label : labels.entrySet())
+                    {
+                        if (comma)
+                            out.append(',');
+                        comma = true;
+                        out.append(label.getKey()).append("=\"").append(escape(String.valueOf(label.getValue()), true)).append('"');
+                    }
+                    out.append('}');
+                }
+                out.append(' ').append(format(metric.getValue())).append('\n');
+            }
+        }
+
+        if (openMetrics)
+            out.append("# EOF\n");
+    }
+
+    private static String escape(String value, boolean quotes)
+    {
+        StringBuilder builder = new StringBuilder(value.length());
+        for (int i = 0; i < value.length(); ++i)
+        {
+            char c = value.charAt(i);
+            switch (c)
+            {
+                case '\\':
+                    builder.append("\\\\");
+                    break;
+                case '\n':
+                    builder.append("\\n");
+                    break;
+                case '"':
+                    builder.append(quotes ? "\\\"" : "\"");
+                    break;
+                default:
+                    builder.append(c);
+                    break;
+            }
+        }
+        return builder.toString();
+    }
+
+    private static String format(double value)
+    {
+        if (Double.isNaN(value))
+            return "NaN";
+        if (Double.isInfinite(value))
+            return value > 0 ? "+Inf" : "-Inf";
+        if (value == Math.rint(value) && Math.abs(value) < 1E15)
+            return Long.toString((long)value);
+        return Double.toString(value);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[collectors=%d,bridges=%d]", getClass().getSimpleName(), hashCode(), collectors.size(), boundBridges.size());
+    }
+}
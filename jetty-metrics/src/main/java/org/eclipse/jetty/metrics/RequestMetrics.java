@@ -0,0 +1,112 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Arrays;
+import java.util.Map;
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandlerContainer;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.StatisticsHandler;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.statistic.HistogramStatistic;
+
+/**
+ * <p>A {@link MetricsCollector} for the requests gathered by the
+ * {@link StatisticsHandler}s of a {@link Server}.</p>
+ * <p>The metrics of a {@link StatisticsHandler} within a context have
+ * a {@code context} label with the context path.</p>
+ * <p>The request time quantiles and the per-path metrics are only available
+ * if the {@link StatisticsHandler} gathers them, see
+ * {@link StatisticsHandler#setRequestTimeWindow(long)} and
+ * {@link StatisticsHandler#setPathDepth(int)}.</p>
+ */
+@ManagedObject("Request metrics")
+public class RequestMetrics implements MetricsCollector
+{
+    private static final double[] QUANTILES = {0.5, 0.95, 0.99};
+
+    private final Server server;
+
+    public RequestMetrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        for (Handler handler : server.getChildHandlersByClass(StatisticsHandler.class))
+        {
+            StatisticsHandler stats = (StatisticsHandler)handler;
+            ContextHandler context = AbstractHandlerContainer.findContainerOf(server, ContextHandler.class, stats);
+            String[] labels = context == null ? new String[0] : new String[]{"context", context.getContextPath()};
+
+            metrics.accept(Metric.counter("jetty_requests", "The number of requests", stats.getRequests(), labels));
+            metrics.accept(Metric.gauge("jetty_requests_active", "The number of requests currently active", stats.getRequestsActive(), labels));
+            metrics.accept(Metric.gauge("jetty_requests_active_max", "The max number of requests active", stats.getRequestsActiveMax(), labels));
+            metrics.accept(Metric.counter("jetty_responses", "The number of responses", stats.getResponses1xx(), with(labels, "status", "1xx")));
+            metrics.accept(Metric.counter("jetty_responses", "The number of responses", stats.getResponses2xx(), with(labels, "status", "2xx")));
+            metrics.accept(Metric.counter("jetty_responses", "The number of responses", stats.getResponses3xx(), with(labels, "status", "3xx")));
+            metrics.accept(Metric.counter("jetty_responses", "The number of responses", stats.getResponses4xx(), with(labels, "status", "4xx")));
+            metrics.accept(Metric.counter("jetty_responses", "The number of responses", stats.getResponses5xx(), with(labels, "status", "5xx")));
+            metrics.accept(Metric.counter("jetty_responses_thrown", "The number of requests that threw an exception", stats.getResponsesThrown(), labels));
+            metrics.accept(Metric.counter("jetty_responses_sent_bytes", "The number of response content bytes", stats.getResponsesBytesTotal(), labels));
+            metrics.accept(Metric.gauge("jetty_request_time_max_seconds", "The max request time", stats.getRequestTimeMax() / 1000.0, labels));
+            metrics.accept(Metric.gauge("jetty_request_time_mean_seconds", "The mean request time", stats.getRequestTimeMean() / 1000.0, labels));
+            quantiles(metrics, "jetty_request_time_seconds", "The request time quantiles over the sliding window", stats.getRequestTimeHistogram(), labels);
+
+            for (Map.Entry<String, StatisticsHandler.PathStatistics> entry : stats.getPathStatistics().entrySet())
+            {
+                String[] pathLabels = with(labels, "path", entry.getKey());
+                StatisticsHandler.PathStatistics pathStats = entry.getValue();
+                metrics.accept(Metric.counter("jetty_path_requests", "The number of requests per path", pathStats.getRequests(), pathLabels));
+                metrics.accept(Metric.counter("jetty_path_failures", "The number of failed requests per path", pathStats.getFailures(), pathLabels));
+                metrics.accept(Metric.gauge("jetty_path_request_time_mean_seconds", "The mean request time per path", pathStats.getRequestTimeMean() / 1000.0, pathLabels));
+                quantiles(metrics, "jetty_path_request_time_seconds", "The request time quantiles per path over the sliding window", pathStats.getRequestTimeHistogram(), pathLabels);
+            }
+        }
+    }
+
+    private static void quantiles(Consumer<Metric> metrics, String name, String help, HistogramStatistic histogram, String[] labels)
+    {
+        if (histogram == null)
+            return;
+        HistogramStatistic.Snapshot snapshot = histogram.snapshot();
+        for (double quantile : QUANTILES)
+        {
+            long millis = snapshot.getPercentile(quantile * 100);
+            metrics.accept(Metric.gauge(name, help, millis / 1000.0, with(labels, "quantile", String.valueOf(quantile))));
+        }
+    }
+
+    private static String[] with(String[] labels, String name, String value)
+    {
+        String[] result = Arrays.copyOf(labels, labels.length + 2);
+        result[labels.length] = name;
+        result[labels.length + 1] = value;
+        return result;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
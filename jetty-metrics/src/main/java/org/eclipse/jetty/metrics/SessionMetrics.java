@@ -0,0 +1,71 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.session.DefaultSessionCache;
+import org.eclipse.jetty.server.session.SessionCache;
+import org.eclipse.jetty.server.session.SessionHandler;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link MetricsCollector} for the {@link SessionHandler}s and
+ * {@link SessionCache}s of the contexts of a {@link Server}.</p>
+ */
+@ManagedObject("Session metrics")
+public class SessionMetrics implements MetricsCollector
+{
+    private final Server server;
+
+    public SessionMetrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        for (Handler handler : server.getChildHandlersByClass(ContextHandler.class))
+        {
+            ContextHandler context = (ContextHandler)handler;
+            SessionHandler sessionHandler = context.getChildHandlerByClass(SessionHandler.class);
+            if (sessionHandler == null)
+                continue;
+            String name = context.getContextPath();
+            metrics.accept(Metric.counter("jetty_sessions_created", "The number of sessions created", sessionHandler.getSessionsCreated(), "context", name));
+            metrics.accept(Metric.gauge("jetty_sessions_time_max_seconds", "The max time sessions have remained valid", sessionHandler.getSessionTimeMax(), "context", name));
+            metrics.accept(Metric.gauge("jetty_sessions_time_mean_seconds", "The mean time sessions have remained valid", sessionHandler.getSessionTimeMean(), "context", name));
+
+            SessionCache cache = sessionHandler.getSessionCache();
+            if (cache instanceof DefaultSessionCache)
+            {
+                DefaultSessionCache defaultCache = (DefaultSessionCache)cache;
+                metrics.accept(Metric.gauge("jetty_session_cache_sessions", "The number of sessions in the cache", defaultCache.getSessionsCurrent(), "context", name));
+                metrics.accept(Metric.gauge("jetty_session_cache_sessions_max", "The max number of sessions in the cache", defaultCache.getSessionsMax(), "context", name));
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
@@ -0,0 +1,60 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.eclipse.jetty.util.thread.ThreadPool;
+
+/**
+ * <p>A {@link MetricsCollector} for the threads of a {@link ThreadPool},
+ * with additional metrics for {@link QueuedThreadPool}s.</p>
+ */
+@ManagedObject("Thread pool metrics")
+public class ThreadPoolMetrics implements MetricsCollector
+{
+    private final ThreadPool threadPool;
+
+    public ThreadPoolMetrics(@Name("threadPool") ThreadPool threadPool)
+    {
+        this.threadPool = Objects.requireNonNull(threadPool);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        String pool = threadPool instanceof QueuedThreadPool ? ((QueuedThreadPool)threadPool).getName() : threadPool.getClass().getSimpleName();
+        metrics.accept(Metric.gauge("jetty_threads", "The number of threads in the pool", threadPool.getThreads(), "pool", pool));
+        metrics.accept(Metric.gauge("jetty_threads_idle", "The number of idle threads in the pool", threadPool.getIdleThreads(), "pool", pool));
+        if (threadPool instanceof ThreadPool.SizedThreadPool)
+            metrics.accept(Metric.gauge("jetty_threads_max", "The max number of threads in the pool", ((ThreadPool.SizedThreadPool)threadPool).getMaxThreads(), "pool", pool));
+        if (threadPool instanceof QueuedThreadPool)
+        {
+            QueuedThreadPool qtp = (QueuedThreadPool)threadPool;
+            metrics.accept(Metric.gauge("jetty_threads_busy", "The number of threads running jobs", qtp.getBusyThreads(), "pool", pool));
+            metrics.accept(Metric.gauge("jetty_threads_queued_jobs", "The number of jobs waiting for a thread", qtp.getQueueSize(), "pool", pool));
+        }
+        metrics.accept(Metric.gauge("jetty_threads_low", "Whether the pool is low on threads", threadPool.isLowOnThreads() ? 1 : 0, "pool", pool));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), threadPool);
+    }
+}
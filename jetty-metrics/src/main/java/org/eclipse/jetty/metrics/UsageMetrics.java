@@ -0,0 +1,106 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.Arrays;
+import java.util.Objects;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandlerContainer;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.UsageAccountingHandler;
+import org.eclipse.jetty.server.handler.UsageQuotaHandler;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link MetricsCollector} for the usage accounted by the
+ * {@link UsageAccountingHandler}s of a {@link Server}, and for the requests
+ * rejected by its {@link UsageQuotaHandler}s.</p>
+ * <p>The usage metrics have a {@code key} label with the accounting key;
+ * the {@code jetty_usage_rollup_*} metrics report the usage of the last
+ * completed accounting period, and the {@code jetty_usage_period_*} metrics
+ * the usage of the current period so far.
+ * The metrics of a handler within a context also have a {@code context}
+ * label with the context path.</p>
+ */
+@ManagedObject("Usage metrics")
+public class UsageMetrics implements MetricsCollector
+{
+    private final Server server;
+
+    public UsageMetrics(@Name("server") Server server)
+    {
+        this.server = Objects.requireNonNull(server);
+    }
+
+    @Override
+    public void collect(Consumer<Metric> metrics)
+    {
+        for (Handler handler : server.getChildHandlersByClass(UsageAccountingHandler.class))
+        {
+            UsageAccountingHandler accounting = (UsageAccountingHandler)handler;
+            String[] context = contextLabels(accounting);
+            metrics.accept(Metric.gauge("jetty_usage_keys", "The number of accounting keys", accounting.getKeys(), context));
+            for (UsageAccountingHandler.Usage usage : accounting.getUsages())
+            {
+                String[] labels = with(context, "key", usage.getKey());
+                metrics.accept(Metric.counter("jetty_usage_requests", "The number of requests", usage.getRequests(), labels));
+                metrics.accept(Metric.counter("jetty_usage_received_bytes", "The number of bytes received", usage.getBytesReceived(), labels));
+                metrics.accept(Metric.counter("jetty_usage_sent_bytes", "The number of bytes sent", usage.getBytesSent(), labels));
+
+                UsageAccountingHandler.Rollup period = usage.getCurrentPeriod();
+                metrics.accept(Metric.gauge("jetty_usage_period_received_bytes", "The number of bytes received in the current period", period.getBytesReceived(), labels));
+                metrics.accept(Metric.gauge("jetty_usage_period_sent_bytes", "The number of bytes sent in the current period", period.getBytesSent(), labels));
+
+                UsageAccountingHandler.Rollup rollup = usage.getLastRollup();
+                if (rollup != null)
+                {
+                    metrics.accept(Metric.gauge("jetty_usage_rollup_requests", "The number of requests in the last period", rollup.getRequests(), labels));
+                    metrics.accept(Metric.gauge("jetty_usage_rollup_received_bytes", "The number of bytes received in the last period", rollup.getBytesReceived(), labels));
+                    metrics.accept(Metric.gauge("jetty_usage_rollup_sent_bytes", "The number of bytes sent in the last period", rollup.getBytesSent(), labels));
+                    metrics.accept(Metric.gauge("jetty_usage_rollup_end_seconds", "The end time of the last period", rollup.getEnd() / 1000.0, labels));
+                }
+            }
+        }
+
+        for (Handler handler : server.getChildHandlersByClass(UsageQuotaHandler.class))
+        {
+            UsageQuotaHandler quota = (UsageQuotaHandler)handler;
+            metrics.accept(Metric.counter("jetty_usage_quota_rejected", "The number of requests rejected over quota", quota.getRequestsRejected(), contextLabels(quota)));
+        }
+    }
+
+    private String[] contextLabels(Handler handler)
+    {
+        ContextHandler context = AbstractHandlerContainer.findContainerOf(server, ContextHandler.class, handler);
+        return context == null ? new String[0] : new String[]{"context", context.getContextPath()};
+    }
+
+    private static String[] with(String[] labels, String name, String value)
+    {
+        String[] result = Arrays.copyOf(labels, labels.length + 2);
+        result[labels.length] = name;
+        result[labels.length + 1] = value;
+        return result;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x", getClass().getSimpleName(), hashCode());
+    }
+}
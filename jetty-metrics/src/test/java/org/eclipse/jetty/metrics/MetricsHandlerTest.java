@@ -0,0 +1,215 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.metrics;
+
+import java.util.List;
+import javax.servlet.http.HttpServlet;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.io.ConnectionStatistics;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.StatisticsHandler;
+import org.eclipse.jetty.server.handler.UsageAccountingHandler;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.servlet.ServletHolder;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.startsWith;
+
+public class MetricsHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private MetricsHandler metricsHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        QueuedThreadPool threadPool = new QueuedThreadPool();
+        threadPool.setName("qtp");
+        server = new Server(threadPool);
+        connector = new LocalConnector(server);
+        connector.setName("local");
+        connector.addBean(new ConnectionStatistics());
+        server.addConnector(connector);
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        context.setContextPath("/ctx");
+        context.addServlet(new ServletHolder(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response)
+            {
+                request.getSession(true);
+            }
+        }), "/session");
+        metricsHandler = new MetricsHandler();
+        metricsHandler.setHandler(context);
+        metricsHandler.addCollector(new ThreadPoolMetrics(threadPool));
+        metricsHandler.addCollector(new ConnectorMetrics(server));
+        metricsHandler.addCollector(new SessionMetrics(server));
+        metricsHandler.addCollector(new ByteBufferPoolMetrics(server));
+        server.setHandler(metricsHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response get(String path, String accept) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            (accept == null ? "" : "Accept: " + accept + "\r\n") +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testOpenMetrics() throws Exception
+    {
+        server.start();
+
+        assertThat(get("/ctx/session", null).getStatus(), is(HttpStatus.OK_200));
+
+        HttpTester.Response response = get("/metrics", "application/openmetrics-text; version=1.0.0,text/plain;q=0.5");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), startsWith("application/openmetrics-text"));
+        assertThat(response.get(HttpHeader.CACHE_CONTROL), is("no-store"));
+        String content = response.getContent();
+        assertThat(content, containsString("# TYPE jetty_threads gauge\n"));
+        assertThat(content, containsString("jetty_threads_max{pool=\"qtp\"} " + ((QueuedThreadPool)server.getThreadPool()).getMaxThreads() + "\n"));
+        assertThat(content, containsString("jetty_connector_connections{connector=\"local\"} "));
+        // The counter family name has no suffix, the sample has.
+        assertThat(content, containsString("# TYPE jetty_connector_connections_opened counter\n"));
+        assertThat(content, containsString("jetty_connector_connections_opened_total{connector=\"local\"} 2\n"));
+        assertThat(content, containsString("jetty_sessions_created_total{context=\"/ctx\"} 1\n"));
+        assertThat(content, containsString("jetty_session_cache_sessions{context=\"/ctx\"} 1\n"));
+        assertThat(content, containsString("jetty_bytebufferpool_memory_bytes{pool="));
+        assertThat(content, endsWith("# EOF\n"));
+    }
+
+    @Test
+    public void testPrometheusText() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = get("/metrics", null);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), startsWith("text/plain; version=0.0.4"));
+        String content = response.getContent();
+        assertThat(content, containsString("# TYPE jetty_connector_connections_opened_total counter\n"));
+        assertThat(content, not(containsString("# EOF")));
+    }
+
+    @Test
+    public void testServerBeanCollector() throws Exception
+    {
+        server.addBean((MetricsCollector)metrics ->
+            metrics.accept(Metric.gauge("app_queue", "The \"app\" queue\nsize", 2.5, "name", "a\"b\\c")));
+        server.start();
+
+        List<Metric> metrics = metricsHandler.getMetricsRegistry().collect();
+        // Server bean collectors come after the registry collectors.
+        assertThat(metrics.get(metrics.size() - 1).getName(), is("app_queue"));
+
+        String content = get("/metrics", "application/openmetrics-text").getContent();
+        assertThat(content, containsString("# HELP app_queue The \\\"app\\\" queue\\nsize\n"));
+        assertThat(content, containsString("app_queue{name=\"a\\\"b\\\\c\"} 2.5\n"));
+    }
+
+    @Test
+    public void testFailingCollectorIsSkipped() throws Exception
+    {
+        MetricsRegistry registry = new MetricsRegistry();
+        registry.addCollector(metrics ->
+        {
+            throw new IllegalStateException("explicitly_thrown_by_test");
+        });
+        registry.addCollector(metrics -> metrics.accept(Metric.counter("requests", null, 3)));
+
+        List<Metric> metrics = registry.collect();
+        assertThat(metrics, hasSize(1));
+        StringBuilder builder = new StringBuilder();
+        registry.write(builder, true);
+        assertThat(builder.toString(), is("# TYPE requests counter\nrequests_total 3\n# EOF\n"));
+    }
+
+    @Test
+    public void testRequestMetrics() throws Exception
+    {
+        StatisticsHandler statsHandler = new StatisticsHandler();
+        statsHandler.setRequestTimeWindow(60000);
+        statsHandler.setPathDepth(1);
+        statsHandler.setHandler(metricsHandler.getHandler());
+        metricsHandler.setHandler(statsHandler);
+        metricsHandler.addCollector(new RequestMetrics(server));
+        server.start();
+
+        assertThat(get("/ctx/session", null).getStatus(), is(HttpStatus.OK_200));
+
+        String content = get("/metrics", "application/openmetrics-text").getContent();
+        assertThat(content, containsString("jetty_requests_total 1\n"));
+        assertThat(content, containsString("jetty_responses_total{status=\"2xx\"} 1\n"));
+        assertThat(content, containsString("jetty_responses_total{status=\"5xx\"} 0\n"));
+        assertThat(content, containsString("jetty_request_time_seconds{quantile=\"0.99\"} "));
+        assertThat(content, containsString("jetty_path_requests_total{path=\"/ctx\"} 1\n"));
+        assertThat(content, containsString("jetty_path_request_time_seconds{path=\"/ctx\",quantile=\"0.5\"} "));
+    }
+
+    @Test
+    public void testUsageMetrics() throws Exception
+    {
+        UsageAccountingHandler accountingHandler = new UsageAccountingHandler();
+        accountingHandler.setKeyExtractor(UsageAccountingHandler.virtualHost());
+        accountingHandler.setHandler(metricsHandler.getHandler());
+        metricsHandler.setHandler(accountingHandler);
+        metricsHandler.addCollector(new UsageMetrics(server));
+        server.start();
+
+        assertThat(get("/ctx/session", null).getStatus(), is(HttpStatus.OK_200));
+
+        UsageAccountingHandler.Usage usage = accountingHandler.getUsage("localhost");
+        String content = get("/metrics", "application/openmetrics-text").getContent();
+        assertThat(content, containsString("jetty_usage_keys 1\n"));
+        assertThat(content, containsString("jetty_usage_requests_total{key=\"localhost\"} 1\n"));
+        assertThat(content, containsString("jetty_usage_received_bytes_total{key=\"localhost\"} " + usage.getBytesReceived() + "\n"));
+        assertThat(content, containsString("jetty_usage_period_sent_bytes{key=\"localhost\"} " + usage.getBytesSent() + "\n"));
+        // No accounting period has completed yet.
+        assertThat(content, not(containsString("jetty_usage_rollup_requests")));
+    }
+
+    @Test
+    public void testOtherRequestsPassThrough() throws Exception
+    {
+        server.start();
+        assertThat(get("/other", null).getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+}
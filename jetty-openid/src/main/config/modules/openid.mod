@@ -48,3 +48,6 @@ etc/jetty-openid.xml
 
 ## Whether the user should be logged out after the idToken expires.
 # jetty.openid.logoutWhenIdTokenIsExpired=false
+
+## Whether to use PKCE (S256) to protect the authorization code flow.
+# jetty.openid.usePkce=true
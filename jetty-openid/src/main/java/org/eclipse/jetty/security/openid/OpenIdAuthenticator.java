@@ -17,22 +17,34 @@ import java.io.IOException;
 import java.io.Serializable;
 import java.math.BigInteger;
 import java.nio.charset.StandardCharsets;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
 import java.security.SecureRandom;
+import java.time.Instant;
+import java.util.Base64;
 import java.util.LinkedHashMap;
+import java.util.List;
 import java.util.Map;
 import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
 import javax.servlet.ServletRequest;
 import javax.servlet.ServletResponse;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 import javax.servlet.http.HttpSession;
+import javax.servlet.http.HttpSessionBindingEvent;
+import javax.servlet.http.HttpSessionBindingListener;
 
+import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http.MimeTypes;
 import org.eclipse.jetty.security.LoginService;
 import org.eclipse.jetty.security.ServerAuthException;
 import org.eclipse.jetty.security.UserAuthentication;
 import org.eclipse.jetty.security.authentication.DeferredAuthentication;
+import org.eclipse.jetty.security.authentication.JsonWebKey;
+import org.eclipse.jetty.security.authentication.JsonWebToken;
 import org.eclipse.jetty.security.authentication.LoginAuthenticator;
 import org.eclipse.jetty.security.authentication.SessionAuthentication;
 import org.eclipse.jetty.server.Authentication;
@@ -40,6 +52,8 @@ import org.eclipse.jetty.server.Authentication.User;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Response;
 import org.eclipse.jetty.server.UserIdentity;
+import org.eclipse.jetty.server.session.Session;
+import org.eclipse.jetty.server.session.SessionHandler;
 import org.eclipse.jetty.util.MultiMap;
 import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.UrlEncoded;
@@ -59,6 +73,12 @@ import org.slf4j.LoggerFactory;
  * The full response containing the OAuth 2.0 Access Token can be obtained with the session attribute {@link #RESPONSE}.
  * </p>
  * <p>{@link SessionAuthentication} is then used to wrap Authentication results so that they are associated with the session.</p>
+ * <p>The authorization code flow is protected with PKCE if {@link OpenIdConfiguration#isUsePkce()} is true.
+ * Logging out redirects the End-User to the end_session_endpoint of the OpenID Provider, if it has one.
+ * If {@link #setBackChannelLogoutPath(String)} is set, the OpenID Provider may also log users out by posting a
+ * logout token to that path, as defined by OpenID Connect Back-Channel Logout 1.0; the sessions matching the
+ * {@code sid} claim, or otherwise the {@code sub} claim, of the logout token are then invalidated.
+ * Only the sessions created by this authenticator instance since it was started can be invalidated this way.</p>
  */
 public class OpenIdAuthenticator extends LoginAuthenticator
 {
@@ -69,6 +89,7 @@ public class OpenIdAuthenticator extends LoginAuthenticator
     public static final String ISSUER = "org.eclipse.jetty.security.openid.issuer";
     public static final String REDIRECT_PATH = "org.eclipse.jetty.security.openid.redirect_path";
     public static final String LOGOUT_REDIRECT_PATH = "org.eclipse.jetty.security.openid.logout_redirect_path";
+    public static final String BACKCHANNEL_LOGOUT_PATH = "org.eclipse.jetty.security.openid.backchannel_logout_path";
     public static final String ERROR_PAGE = "org.eclipse.jetty.security.openid.error_page";
     public static final String J_URI = "org.eclipse.jetty.security.openid.URI";
     public static final String J_POST = "org.eclipse.jetty.security.openid.POST";
@@ -76,14 +97,19 @@ public class OpenIdAuthenticator extends LoginAuthenticator
     public static final String J_SECURITY_CHECK = "/j_security_check";
     public static final String ERROR_PARAMETER = "error_description_jetty";
     private static final String CSRF_MAP = "org.eclipse.jetty.security.openid.csrf_map";
+    private static final String SESSION_REGISTRATION = "org.eclipse.jetty.security.openid.session_registration";
+    private static final String BACKCHANNEL_LOGOUT_EVENT = "http://schemas.openid.net/event/backchannel-logout";
 
     @Deprecated
     public static final String CSRF_TOKEN = "org.eclipse.jetty.security.openid.csrf_token";
 
     private final SecureRandom _secureRandom = new SecureRandom();
+    private final Map<String, Set<String>> _sessionsBySid = new ConcurrentHashMap<>();
+    private final Map<String, Set<String>> _sessionsBySub = new ConcurrentHashMap<>();
     private OpenIdConfiguration _openIdConfiguration;
     private String _redirectPath;
     private String _logoutRedirectPath;
+    private String _backChannelLogoutPath;
     private String _errorPage;
     private String _errorPath;
     private String _errorQuery;
@@ -142,6 +168,10 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         if (logout != null)
             setLogoutRedirectPath(logout);
 
+        String backChannelLogout = authConfig.getInitParameter(BACKCHANNEL_LOGOUT_PATH);
+        if (backChannelLogout != null)
+            setBackChannelLogoutPath(backChannelLogout);
+
         super.setConfiguration(new OpenIdAuthConfiguration(_openIdConfiguration, authConfig));
     }
 
@@ -195,6 +225,30 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         _logoutRedirectPath = logoutRedirectPath;
     }
 
+    /**
+     * @return the path within the context where the OpenID Provider posts back-channel logout tokens, or null
+     */
+    public String getBackChannelLogoutPath()
+    {
+        return _backChannelLogoutPath;
+    }
+
+    /**
+     * <p>Sets the path within the context where the OpenID Provider posts back-channel logout tokens,
+     * which must be registered as the {@code backchannel_logout_uri} of the client with the OpenID Provider.</p>
+     *
+     * @param backChannelLogoutPath the back-channel logout path, or null to disable back-channel logout
+     */
+    public void setBackChannelLogoutPath(String backChannelLogoutPath)
+    {
+        if (backChannelLogoutPath != null && !backChannelLogoutPath.startsWith("/"))
+        {
+            LOG.warn("back-channel logout path must start with /");
+            backChannelLogoutPath = "/" + backChannelLogoutPath;
+        }
+        _backChannelLogoutPath = backChannelLogoutPath;
+    }
+
     public void setErrorPage(String path)
     {
         if (path == null || path.trim().length() == 0)
@@ -239,11 +293,35 @@ public class OpenIdAuthenticator extends LoginAuthenticator
                 session.setAttribute(CLAIMS, ((OpenIdCredentials)credentials).getClaims());
                 session.setAttribute(RESPONSE, ((OpenIdCredentials)credentials).getResponse());
                 session.setAttribute(ISSUER, _openIdConfiguration.getIssuer());
+                if (_backChannelLogoutPath != null)
+                    registerSession(session, ((OpenIdCredentials)credentials).getClaims());
             }
         }
         return user;
     }
 
+    private void registerSession(HttpSession session, Map<String, Object> claims)
+    {
+        Object sid = claims.get("sid");
+        Object sub = claims.get("sub");
+        SessionRegistration registration = new SessionRegistration(this, sid instanceof String ? (String)sid : null,
+            sub instanceof String ? (String)sub : null, session.getId());
+        // Replacing a previous registration unregisters it, so register afterwards.
+        session.setAttribute(SESSION_REGISTRATION, registration);
+        if (registration._sid != null)
+            _sessionsBySid.computeIfAbsent(registration._sid, k -> ConcurrentHashMap.newKeySet()).add(registration._sessionId);
+        if (registration._sub != null)
+            _sessionsBySub.computeIfAbsent(registration._sub, k -> ConcurrentHashMap.newKeySet()).add(registration._sessionId);
+    }
+
+    private void unregisterSession(SessionRegistration registration)
+    {
+        if (registration._sid != null)
+            _sessionsBySid.computeIfPresent(registration._sid, (k, ids) -> ids.remove(registration._sessionId) && ids.isEmpty() ? null : ids);
+        if (registration._sub != null)
+            _sessionsBySub.computeIfPresent(registration._sub, (k, ids) -> ids.remove(registration._sessionId) && ids.isEmpty() ? null : ids);
+    }
+
     @Override
     public void logout(ServletRequest request)
     {
@@ -266,6 +344,7 @@ public class OpenIdAuthenticator extends LoginAuthenticator
             session.removeAttribute(CLAIMS);
             session.removeAttribute(RESPONSE);
             session.removeAttribute(ISSUER);
+            session.removeAttribute(SESSION_REGISTRATION);
         }
     }
 
@@ -315,8 +394,9 @@ public class OpenIdAuthenticator extends LoginAuthenticator
 
             @SuppressWarnings("rawtypes")
             String idToken = (String)((Map)openIdResponse).get("id_token");
-            baseResponse.sendRedirect(endSessionEndpoint +
-                    "?id_token_hint=" + UrlEncoded.encodeString(idToken, StandardCharsets.UTF_8) +
+            baseResponse.sendRedirect(endSessionEndpoint + (endSessionEndpoint.indexOf('?') < 0 ? "?" : "&") +
+                    "id_token_hint=" + UrlEncoded.encodeString(idToken, StandardCharsets.UTF_8) +
+                    "&client_id=" + UrlEncoded.encodeString(_openIdConfiguration.getClientId(), StandardCharsets.UTF_8) +
                     ((redirectUri == null) ? "" : "&post_logout_redirect_uri=" + UrlEncoded.encodeString(redirectUri, StandardCharsets.UTF_8)),
                 true);
         }
@@ -397,6 +477,18 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         if (uri == null)
             uri = URIUtil.SLASH;
 
+        if (_backChannelLogoutPath != null && _backChannelLogoutPath.equals(baseRequest.getPathInContext()))
+        {
+            try
+            {
+                return backChannelLogout(baseRequest, response);
+            }
+            catch (IOException e)
+            {
+                throw new ServerAuthException(e);
+            }
+        }
+
         HttpSession session = request.getSession(false);
         if (_openIdConfiguration.isLogoutWhenIdTokenIsExpired() && hasExpiredIdToken(session))
         {
@@ -457,7 +549,7 @@ public class OpenIdAuthenticator extends LoginAuthenticator
                 }
 
                 // Attempt to login with the provided authCode.
-                OpenIdCredentials credentials = new OpenIdCredentials(authCode, getRedirectUri(request));
+                OpenIdCredentials credentials = new OpenIdCredentials(authCode, getRedirectUri(request), uriRedirectInfo.getCodeVerifier());
                 UserIdentity user = login(null, credentials, request);
                 if (user == null)
                 {
@@ -552,6 +644,98 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         }
     }
 
+    /**
+     * <p>Handles a back-channel logout request from the OpenID Provider, invalidating
+     * the sessions of the End-User identified by the logout token.</p>
+     *
+     * @param request the back-channel logout request
+     * @param response the back-channel logout response
+     * @return the authentication result, after the response has been sent
+     * @throws IOException if sending the response fails
+     */
+    private Authentication backChannelLogout(Request request, HttpServletResponse response) throws IOException
+    {
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), "no-store");
+        if (!HttpMethod.POST.is(request.getMethod()))
+        {
+            response.sendError(HttpServletResponse.SC_METHOD_NOT_ALLOWED);
+            return Authentication.SEND_FAILURE;
+        }
+
+        JsonWebToken logoutToken;
+        try
+        {
+            logoutToken = validateLogoutToken(request.getParameter("logout_token"));
+        }
+        catch (Exception x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("invalid logout token", x);
+            response.sendError(HttpServletResponse.SC_BAD_REQUEST);
+            return Authentication.SEND_FAILURE;
+        }
+
+        Object sid = logoutToken.getClaim("sid");
+        Set<String> sessionIds = sid instanceof String
+            ? _sessionsBySid.get(sid)
+            : _sessionsBySub.get(logoutToken.getSubject());
+        SessionHandler sessionHandler = request.getSessionHandler();
+        if (sessionIds != null && sessionHandler != null)
+        {
+            for (String sessionId : List.copyOf(sessionIds))
+            {
+                Session session = sessionHandler.getSession(sessionId);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("back-channel logout {} {}", sessionId, session);
+                if (session == null)
+                    continue;
+                try
+                {
+                    session.invalidate();
+                }
+                catch (IllegalStateException x)
+                {
+                    // Already invalidated.
+                }
+            }
+        }
+
+        response.setStatus(HttpServletResponse.SC_OK);
+        response.setContentLength(0);
+        return Authentication.SEND_SUCCESS;
+    }
+
+    private JsonWebToken validateLogoutToken(String token) throws Exception
+    {
+        if (token == null)
+            throw new OpenIdCredentials.AuthenticationException("no logout_token");
+        JsonWebToken logoutToken = JsonWebToken.parse(token);
+        String algorithm = logoutToken.getAlgorithm();
+        if (!JsonWebToken.RS256.equals(algorithm) && !JsonWebToken.ES256.equals(algorithm) && !JsonWebToken.HS256.equals(algorithm))
+            throw new OpenIdCredentials.AuthenticationException("unsupported algorithm " + algorithm);
+        JsonWebKey key = _openIdConfiguration.getKeyProvider().getKey(logoutToken);
+        if (key == null || !key.isCompatible(algorithm) || !logoutToken.verify(key.getKey()))
+            throw new OpenIdCredentials.AuthenticationException("invalid signature");
+
+        if (!_openIdConfiguration.getIssuer().equals(logoutToken.getIssuer()))
+            throw new OpenIdCredentials.AuthenticationException("invalid issuer");
+        if (!logoutToken.getAudience().contains(_openIdConfiguration.getClientId()))
+            throw new OpenIdCredentials.AuthenticationException("invalid audience");
+        if (logoutToken.getIssuedAt() == null)
+            throw new OpenIdCredentials.AuthenticationException("no iat claim");
+        Instant expiresAt = logoutToken.getExpiresAt();
+        if (expiresAt != null && expiresAt.isBefore(Instant.now()))
+            throw new OpenIdCredentials.AuthenticationException("expired logout token");
+        Object events = logoutToken.getClaim("events");
+        if (!(events instanceof Map) || !((Map<?, ?>)events).containsKey(BACKCHANNEL_LOGOUT_EVENT))
+            throw new OpenIdCredentials.AuthenticationException("no back-channel logout event");
+        if (logoutToken.getClaim("sid") == null && logoutToken.getSubject() == null)
+            throw new OpenIdCredentials.AuthenticationException("no sid nor sub claim");
+        if (logoutToken.getClaim("nonce") != null)
+            throw new OpenIdCredentials.AuthenticationException("nonce claim not allowed");
+        return logoutToken;
+    }
+
     /**
      * Report an error case either by redirecting to the error page if it is defined, otherwise sending a 403 response.
      * If the message parameter is not null, a query parameter with a key of {@link #ERROR_PARAMETER} and value of the error
@@ -624,11 +808,12 @@ public class OpenIdAuthenticator extends LoginAuthenticator
     {
         HttpSession session = request.getSession();
         String antiForgeryToken;
+        String codeVerifier = _openIdConfiguration.isUsePkce() ? newCodeVerifier() : null;
         synchronized (session)
         {
             Map<String, UriRedirectInfo> csrfMap = ensureCsrfMap(session);
             antiForgeryToken = new BigInteger(130, _secureRandom).toString(32);
-            csrfMap.put(antiForgeryToken, new UriRedirectInfo(request));
+            csrfMap.put(antiForgeryToken, new UriRedirectInfo(request, codeVerifier));
         }
 
         // any custom scopes requested from configuration
@@ -643,7 +828,28 @@ public class OpenIdAuthenticator extends LoginAuthenticator
             "&redirect_uri=" + UrlEncoded.encodeString(getRedirectUri(request), StandardCharsets.UTF_8) +
             "&scope=openid" + UrlEncoded.encodeString(scopes.toString(), StandardCharsets.UTF_8) +
             "&state=" + antiForgeryToken +
-            "&response_type=code";
+            "&response_type=code" +
+            ((codeVerifier == null) ? "" : "&code_challenge=" + getCodeChallenge(codeVerifier) + "&code_challenge_method=S256");
+    }
+
+    private String newCodeVerifier()
+    {
+        byte[] bytes = new byte[32];
+        _secureRandom.nextBytes(bytes);
+        return Base64.getUrlEncoder().withoutPadding().encodeToString(bytes);
+    }
+
+    private static String getCodeChallenge(String codeVerifier)
+    {
+        try
+        {
+            byte[] digest = MessageDigest.getInstance("SHA-256").digest(codeVerifier.getBytes(StandardCharsets.US_ASCII));
+            return Base64.getUrlEncoder().withoutPadding().encodeToString(digest);
+        }
+        catch (NoSuchAlgorithmException x)
+        {
+            throw new IllegalStateException(x);
+        }
     }
 
     @Override
@@ -701,11 +907,13 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         private final String _uri;
         private final String _method;
         private final MultiMap<String> _formParameters;
+        private final String _codeVerifier;
 
-        public UriRedirectInfo(Request request)
+        public UriRedirectInfo(Request request, String codeVerifier)
         {
             _uri = request.getRequestURI();
             _method = request.getMethod();
+            _codeVerifier = codeVerifier;
 
             if (MimeTypes.Type.FORM_ENCODED.is(request.getContentType()) && HttpMethod.POST.is(request.getMethod()))
             {
@@ -733,6 +941,37 @@ public class OpenIdAuthenticator extends LoginAuthenticator
         {
             return _formParameters;
         }
+
+        public String getCodeVerifier()
+        {
+            return _codeVerifier;
+        }
+    }
+
+    private static class SessionRegistration implements HttpSessionBindingListener, Serializable
+    {
+        private static final long serialVersionUID = 4606278783800574522L;
+
+        private final transient OpenIdAuthenticator _authenticator;
+        private final String _sid;
+        private final String _sub;
+        private final String _sessionId;
+
+        private SessionRegistration(OpenIdAuthenticator authenticator, String sid, String sub, String sessionId)
+        {
+            _authenticator = authenticator;
+            _sid = sid;
+            _sub = sub;
+            _sessionId = sessionId;
+        }
+
+        @Override
+        public void valueUnbound(HttpSessionBindingEvent event)
+        {
+            // The authenticator is not restored when the session is deserialized.
+            if (_authenticator != null)
+                _authenticator.unregisterSession(this);
+        }
     }
 
     /**
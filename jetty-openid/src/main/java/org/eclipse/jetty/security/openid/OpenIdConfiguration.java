@@ -13,16 +13,28 @@
 
 package org.eclipse.jetty.security.openid;
 
+import java.io.IOException;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
 import java.util.Collections;
 import java.util.List;
 import java.util.Map;
 import java.util.Objects;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
 import java.util.stream.Collectors;
 
 import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
 import org.eclipse.jetty.client.http.HttpClientTransportOverHTTP;
+import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.security.authentication.JsonWebKey;
+import org.eclipse.jetty.security.authentication.JsonWebToken;
+import org.eclipse.jetty.security.authentication.JwksKeyProvider;
+import org.eclipse.jetty.security.authentication.JwtKeyProvider;
 import org.eclipse.jetty.util.ajax.JSON;
 import org.eclipse.jetty.util.annotation.Name;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
@@ -43,6 +55,7 @@ public class OpenIdConfiguration extends ContainerLifeCycle
     private static final String AUTHORIZATION_ENDPOINT = "authorization_endpoint";
     private static final String TOKEN_ENDPOINT = "token_endpoint";
     private static final String END_SESSION_ENDPOINT = "end_session_endpoint";
+    private static final String JWKS_URI = "jwks_uri";
     private static final String ISSUER = "issuer";
 
     private final HttpClient httpClient;
@@ -54,8 +67,11 @@ public class OpenIdConfiguration extends ContainerLifeCycle
     private String authEndpoint;
     private String tokenEndpoint;
     private String endSessionEndpoint;
+    private String jwksUri;
+    private JwtKeyProvider keyProvider;
     private boolean authenticateNewUsers = false;
     private boolean logoutWhenIdTokenIsExpired = false;
+    private boolean usePkce = true;
 
     /**
      * Create an OpenID configuration for a specific OIDC provider.
@@ -171,6 +187,10 @@ public class OpenIdConfiguration extends ContainerLifeCycle
         if (endSessionEndpoint == null)
             endSessionEndpoint = (String)discoveryDocument.get(END_SESSION_ENDPOINT);
 
+        // The JWK Set is optional, it is only needed to verify back-channel logout tokens.
+        if (jwksUri == null)
+            jwksUri = (String)discoveryDocument.get(JWKS_URI);
+
         // We are lenient and not throw here as some major OIDC providers do not conform to this.
         if (!Objects.equals(discoveryDocument.get(ISSUER), issuer))
             LOG.warn("The issuer in the metadata is not correct.");
@@ -255,6 +275,86 @@ public class OpenIdConfiguration extends ContainerLifeCycle
         return authMethod;
     }
 
+    /**
+     * @return the URI of the JWK Set of the OpenID Provider, or null if unknown
+     */
+    public String getJwksUri()
+    {
+        return jwksUri;
+    }
+
+    /**
+     * <p>Sets the URI of the JWK Set of the OpenID Provider, which otherwise
+     * is discovered from the OpenID Provider metadata.</p>
+     *
+     * @param jwksUri the URI of the JWK Set of the OpenID Provider
+     */
+    public void setJwksUri(String jwksUri)
+    {
+        this.jwksUri = jwksUri;
+    }
+
+    /**
+     * <p>Returns the provider of the keys of the OpenID Provider, used to verify the
+     * signature of the tokens that are not received directly from the token endpoint.</p>
+     * <p>The keys are fetched from the {@link #getJwksUri() JWK Set} using the
+     * {@link #getHttpClient() HttpClient} of this configuration.
+     * Tokens signed with {@code HS256} are verified with the client secret.
+     * If the JWK Set URI is not known, only tokens signed with {@code HS256} can be verified.</p>
+     *
+     * @return the provider of the keys of the OpenID Provider
+     */
+    public synchronized JwtKeyProvider getKeyProvider()
+    {
+        if (keyProvider == null)
+        {
+            JwtKeyProvider jwks = jwksUri == null ? JwtKeyProvider.of() : new JwksKeyProvider(URI.create(jwksUri))
+            {
+                @Override
+                protected String fetch(URI uri) throws IOException
+                {
+                    try
+                    {
+                        ContentResponse response = httpClient.newRequest(uri)
+                            .timeout(getReadTimeout(), TimeUnit.MILLISECONDS)
+                            .send();
+                        if (response.getStatus() != HttpStatus.OK_200)
+                            throw new IOException("Unexpected response " + response.getStatus() + " from " + uri);
+                        return response.getContentAsString();
+                    }
+                    catch (InterruptedException | TimeoutException | ExecutionException x)
+                    {
+                        throw new IOException("Could not fetch " + uri, x);
+                    }
+                }
+            };
+            JsonWebKey secret = clientSecret == null ? null : JsonWebKey.hmac(null, clientSecret.getBytes(StandardCharsets.UTF_8));
+            keyProvider = token -> JsonWebToken.HS256.equals(token.getAlgorithm()) ? secret : jwks.getKey(token);
+        }
+        return keyProvider;
+    }
+
+    /**
+     * @return whether the authorization code flow uses PKCE
+     * @see #setUsePkce(boolean)
+     */
+    public boolean isUsePkce()
+    {
+        return usePkce;
+    }
+
+    /**
+     * <p>Sets whether the authorization code flow uses Proof Key for Code Exchange
+     * (<a href="https://datatracker.ietf.org/doc/html/rfc7636">RFC 7636</a>)
+     * with the {@code S256} challenge method.</p>
+     *
+     * @param usePkce whether the authorization code flow uses PKCE
+     */
+    public void setUsePkce(boolean usePkce)
+    {
+        this.usePkce = usePkce;
+    }
+
     public void addScopes(String... scopes)
     {
         if (scopes != null)
@@ -296,7 +396,7 @@ public class OpenIdConfiguration extends ContainerLifeCycle
     @Override
     public String toString()
     {
-        return String.format("%s@%x{iss=%s, clientId=%s, authEndpoint=%s, authMethod=%s, tokenEndpoint=%s, scopes=%s, authNewUsers=%s}",
-            getClass().getSimpleName(), hashCode(), issuer, clientId, authEndpoint, authMethod, tokenEndpoint, scopes, authenticateNewUsers);
+        return String.format("%s@%x{iss=%s, clientId=%s, authEndpoint=%s, authMethod=%s, tokenEndpoint=%s, scopes=%s, authNewUsers=%s, pkce=%s}",
+            getClass().getSimpleName(), hashCode(), issuer, clientId, authEndpoint, authMethod, tokenEndpoint, scopes, authenticateNewUsers, usePkce);
     }
 }
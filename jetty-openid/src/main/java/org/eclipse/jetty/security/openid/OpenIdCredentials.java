@@ -47,6 +47,7 @@ public class OpenIdCredentials implements Serializable
     private static final long serialVersionUID = 4766053233370044796L;
 
     private final String redirectUri;
+    private final String codeVerifier;
     private String authCode;
     private Map<String, Object> response;
     private Map<String, Object> claims;
@@ -55,14 +56,26 @@ public class OpenIdCredentials implements Serializable
     public OpenIdCredentials(Map<String, Object> claims)
     {
         this.redirectUri = null;
+        this.codeVerifier = null;
         this.authCode = null;
         this.claims = claims;
     }
 
     public OpenIdCredentials(String authCode, String redirectUri)
+    {
+        this(authCode, redirectUri, null);
+    }
+
+    /**
+     * @param authCode the authorization code
+     * @param redirectUri the redirect URI of the authorization request
+     * @param codeVerifier the PKCE code verifier of the authorization request, or null if PKCE was not used
+     */
+    public OpenIdCredentials(String authCode, String redirectUri, String codeVerifier)
     {
         this.authCode = authCode;
         this.redirectUri = redirectUri;
+        this.codeVerifier = codeVerifier;
     }
 
     public String getUserId()
@@ -186,6 +199,8 @@ public class OpenIdCredentials implements Serializable
         fields.add("code", authCode);
         fields.add("redirect_uri", redirectUri);
         fields.add("grant_type", "authorization_code");
+        if (codeVerifier != null)
+            fields.add("code_verifier", codeVerifier);
 
         Request request = configuration.getHttpClient().POST(configuration.getTokenEndpoint());
         switch (configuration.getAuthMethod())
@@ -13,7 +13,11 @@
 
 package org.eclipse.jetty.security.openid;
 
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
 import java.util.Base64;
+import javax.crypto.Mac;
+import javax.crypto.spec.SecretKeySpec;
 
 /**
  * A basic JWT encoder for testing purposes.
@@ -31,6 +35,20 @@ public class JwtEncoder
             stripPadding(ENCODER.encodeToString(DEFAULT_SIGNATURE.getBytes()));
     }
 
+    /**
+     * Create a JWT signed with HS256 using the given secret.
+     */
+    public static String encodeHs256(String claims, String secret) throws GeneralSecurityException
+    {
+        String header = "{\"alg\": \"HS256\", \"typ\": \"JWT\"}";
+        String signingInput = stripPadding(ENCODER.encodeToString(header.getBytes())) + "." +
+            stripPadding(ENCODER.encodeToString(claims.getBytes()));
+        Mac mac = Mac.getInstance("HmacSHA256");
+        mac.init(new SecretKeySpec(secret.getBytes(StandardCharsets.UTF_8), "HmacSHA256"));
+        byte[] signature = mac.doFinal(signingInput.getBytes(StandardCharsets.US_ASCII));
+        return signingInput + "." + stripPadding(ENCODER.encodeToString(signature));
+    }
+
     private static String stripPadding(String paddedBase64)
     {
         return paddedBase64.split("=")[0];
@@ -40,9 +58,18 @@ public class JwtEncoder
      * Create a basic JWT for testing using argument supplied attributes.
      */
     public static String createIdToken(String provider, String clientId, String subject, String name, long expiry)
+    {
+        return createIdToken(provider, clientId, subject, name, expiry, null);
+    }
+
+    /**
+     * Create a basic JWT for testing using argument supplied attributes, with an optional session ID.
+     */
+    public static String createIdToken(String provider, String clientId, String subject, String name, long expiry, String sid)
     {
         return "{" +
             "\"iss\": \"" + provider + "\"," +
+            (sid == null ? "" : "\"sid\": \"" + sid + "\",") +
             "\"sub\": \"" + subject + "\"," +
             "\"aud\": \"" + clientId + "\"," +
             "\"exp\": " + expiry + "," +
@@ -27,6 +27,7 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.client.HttpClient;
 import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.util.FormRequestContent;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.security.AbstractLoginService;
@@ -41,6 +42,7 @@ import org.eclipse.jetty.server.UserIdentity;
 import org.eclipse.jetty.server.session.FileSessionDataStoreFactory;
 import org.eclipse.jetty.servlet.ServletContextHandler;
 import org.eclipse.jetty.toolchain.test.MavenTestingUtils;
+import org.eclipse.jetty.util.Fields;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.security.Constraint;
 import org.eclipse.jetty.util.security.Password;
@@ -127,6 +129,7 @@ public class OpenIdAuthenticationTest
         securityHandler.setInitParameter(OpenIdAuthenticator.REDIRECT_PATH, "/redirect_path");
         securityHandler.setInitParameter(OpenIdAuthenticator.ERROR_PAGE, "/error");
         securityHandler.setInitParameter(OpenIdAuthenticator.LOGOUT_REDIRECT_PATH, "/");
+        securityHandler.setInitParameter(OpenIdAuthenticator.BACKCHANNEL_LOGOUT_PATH, "/backchannel_logout");
         context.setSecurityHandler(securityHandler);
 
         File datastoreDir = MavenTestingUtils.getTargetTestingDir("datastore");
@@ -207,6 +210,79 @@ public class OpenIdAuthenticationTest
         assertThat(openIdProvider.getLoggedInUsers().getTotal(), equalTo(1L));
     }
 
+    @Test
+    public void testPkce() throws Exception
+    {
+        setup(null);
+        openIdProvider.setRequirePkce(true);
+        openIdProvider.setUser(new OpenIdProvider.User("123456789", "Alice"));
+
+        String appUriString = "http://localhost:" + connector.getLocalPort();
+
+        // The authorization code can only be exchanged with the code verifier.
+        ContentResponse response = client.GET(appUriString + "/login");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), containsString("success"));
+
+        response = client.GET(appUriString + "/");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), containsString("userId: 123456789"));
+    }
+
+    @Test
+    public void testPkceDisabled() throws Exception
+    {
+        setup(null, config -> config.setUsePkce(false));
+        openIdProvider.setRequirePkce(true);
+        openIdProvider.setUser(new OpenIdProvider.User("123456789", "Alice"));
+
+        String appUriString = "http://localhost:" + connector.getLocalPort();
+
+        // The provider rejects the authorization request without a code challenge.
+        ContentResponse response = client.GET(appUriString + "/login");
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(response.getContentAsString(), containsString("no code_challenge"));
+    }
+
+    @Test
+    public void testBackChannelLogout() throws Exception
+    {
+        setup(null);
+        openIdProvider.setUser(new OpenIdProvider.User("123456789", "Alice"));
+
+        String appUriString = "http://localhost:" + connector.getLocalPort();
+
+        ContentResponse response = client.GET(appUriString + "/login");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), containsString("success"));
+        response = client.GET(appUriString + "/");
+        assertThat(response.getContentAsString(), containsString("userId: 123456789"));
+
+        // An invalid logout token is rejected.
+        Fields fields = new Fields();
+        fields.put("logout_token", JwtEncoder.encode("{\"iss\": \"" + openIdProvider.getProvider() + "\"}"));
+        response = client.POST(appUriString + "/backchannel_logout")
+            .body(new FormRequestContent(fields))
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(response.getHeaders().get(HttpHeader.CACHE_CONTROL), is("no-store"));
+        response = client.GET(appUriString + "/");
+        assertThat(response.getContentAsString(), containsString("userId: 123456789"));
+
+        // The provider logs out the session identified by the sid claim.
+        fields = new Fields();
+        fields.put("logout_token", openIdProvider.createLogoutToken("123456789"));
+        response = client.POST(appUriString + "/backchannel_logout")
+            .body(new FormRequestContent(fields))
+            .send();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getHeaders().get(HttpHeader.CACHE_CONTROL), is("no-store"));
+
+        response = client.GET(appUriString + "/");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), containsString("not authenticated"));
+    }
+
     @Test
     public void testNestedLoginService() throws Exception
     {
@@ -15,10 +15,15 @@ package org.eclipse.jetty.security.openid;
 
 import java.io.IOException;
 import java.io.PrintWriter;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
 import java.time.Duration;
 import java.time.Instant;
 import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.Base64;
 import java.util.Collections;
 import java.util.HashMap;
 import java.util.List;
@@ -51,6 +56,8 @@ public class OpenIdProvider extends ContainerLifeCycle
     private static final String TOKEN_PATH = "/token";
     private static final String END_SESSION_PATH = "/end_session";
     private final Map<String, User> issuedAuthCodes = new HashMap<>();
+    private final Map<String, String> codeChallenges = new HashMap<>();
+    private final Map<String, String> sessionIds = new HashMap<>();
 
     protected final String clientId;
     protected final String clientSecret;
@@ -62,6 +69,7 @@ public class OpenIdProvider extends ContainerLifeCycle
     private User preAuthedUser;
     private final CounterStatistic loggedInUsers = new CounterStatistic();
     private long _idTokenDuration = Duration.ofSeconds(10).toMillis();
+    private boolean _requirePkce;
 
     public static void main(String[] args) throws Exception
     {
@@ -109,6 +117,28 @@ public class OpenIdProvider extends ContainerLifeCycle
         _idTokenDuration = duration;
     }
 
+    public void setRequirePkce(boolean requirePkce)
+    {
+        _requirePkce = requirePkce;
+    }
+
+    /**
+     * Create a back-channel logout token for the last session of the given subject, signed with the client secret.
+     */
+    public String createLogoutToken(String subject) throws GeneralSecurityException
+    {
+        String sid = sessionIds.get(subject);
+        String claims = "{" +
+            "\"iss\": \"" + provider + "\"," +
+            "\"aud\": \"" + clientId + "\"," +
+            "\"iat\": " + Instant.now().getEpochSecond() + "," +
+            "\"jti\": \"" + UUID.randomUUID() + "\"," +
+            (sid == null ? "\"sub\": \"" + subject + "\"," : "\"sid\": \"" + sid + "\",") +
+            "\"events\": {\"http://schemas.openid.net/event/backchannel-logout\": {}}" +
+            "}";
+        return JwtEncoder.encodeHs256(claims, clientSecret);
+    }
+
     public long getIdTokenDuration()
     {
         return _idTokenDuration;
@@ -204,6 +234,18 @@ public class OpenIdProvider extends ContainerLifeCycle
                 return;
             }
 
+            String codeChallenge = req.getParameter("code_challenge");
+            if (codeChallenge != null && !"S256".equals(req.getParameter("code_challenge_method")))
+            {
+                resp.sendError(HttpServletResponse.SC_FORBIDDEN, "code_challenge_method must be S256");
+                return;
+            }
+            if (codeChallenge == null && _requirePkce)
+            {
+                resp.sendError(HttpServletResponse.SC_FORBIDDEN, "no code_challenge");
+                return;
+            }
+
             if (preAuthedUser == null)
             {
                 PrintWriter writer = resp.getWriter();
@@ -213,12 +255,14 @@ public class OpenIdProvider extends ContainerLifeCycle
                 writer.println("<input type=\"text\" autocomplete=\"off\" placeholder=\"Username\" name=\"username\" required>");
                 writer.println("<input type=\"hidden\" name=\"redirectUri\" value=\"" + redirectUri + "\">");
                 writer.println("<input type=\"hidden\" name=\"state\" value=\"" + state + "\">");
+                if (codeChallenge != null)
+                    writer.println("<input type=\"hidden\" name=\"code_challenge\" value=\"" + codeChallenge + "\">");
                 writer.println("<input type=\"submit\">");
                 writer.println("</form>");
             }
             else
             {
-                redirectUser(req, preAuthedUser, redirectUri, state);
+                redirectUser(req, preAuthedUser, redirectUri, state, codeChallenge);
             }
         }
 
@@ -247,13 +291,15 @@ public class OpenIdProvider extends ContainerLifeCycle
             }
 
             User user = new User(username);
-            redirectUser(req, user, redirectUri, state);
+            redirectUser(req, user, redirectUri, state, req.getParameter("code_challenge"));
         }
 
-        public void redirectUser(HttpServletRequest request, User user, String redirectUri, String state) throws IOException
+        public void redirectUser(HttpServletRequest request, User user, String redirectUri, String state, String codeChallenge) throws IOException
         {
             String authCode = UUID.randomUUID().toString().replace("-", "");
             issuedAuthCodes.put(authCode, user);
+            if (codeChallenge != null)
+                codeChallenges.put(authCode, codeChallenge);
 
             try
             {
@@ -267,6 +313,7 @@ public class OpenIdProvider extends ContainerLifeCycle
             catch (Throwable t)
             {
                 issuedAuthCodes.remove(authCode);
+                codeChallenges.remove(authCode);
                 throw t;
             }
         }
@@ -296,11 +343,21 @@ public class OpenIdProvider extends ContainerLifeCycle
                 return;
             }
 
+            String codeChallenge = codeChallenges.remove(code);
+            if (codeChallenge != null && !codeChallenge.equals(getCodeChallenge(req.getParameter("code_verifier"))))
+            {
+                resp.sendError(HttpServletResponse.SC_FORBIDDEN, "invalid code_verifier");
+                return;
+            }
+
+            String sid = UUID.randomUUID().toString();
+            sessionIds.put(user.getSubject(), sid);
+
             String accessToken = "ABCDEFG";
             long accessTokenDuration = Duration.ofMinutes(10).toSeconds();
             String response = "{" +
                 "\"access_token\": \"" + accessToken + "\"," +
-                "\"id_token\": \"" + JwtEncoder.encode(user.getIdToken(provider, clientId, _idTokenDuration)) + "\"," +
+                "\"id_token\": \"" + JwtEncoder.encode(user.getIdToken(provider, clientId, _idTokenDuration, sid)) + "\"," +
                 "\"expires_in\": " + accessTokenDuration + "," +
                 "\"token_type\": \"Bearer\"" +
                 "}";
@@ -309,6 +366,21 @@ public class OpenIdProvider extends ContainerLifeCycle
             resp.setContentType("text/plain");
             resp.getWriter().print(response);
         }
+
+        private String getCodeChallenge(String codeVerifier) throws ServletException
+        {
+            if (codeVerifier == null)
+                return null;
+            try
+            {
+                byte[] digest = MessageDigest.getInstance("SHA-256").digest(codeVerifier.getBytes(StandardCharsets.US_ASCII));
+                return Base64.getUrlEncoder().withoutPadding().encodeToString(digest);
+            }
+            catch (NoSuchAlgorithmException x)
+            {
+                throw new ServletException(x);
+            }
+        }
     }
 
     private class EndSessionEndpoint extends HttpServlet
@@ -386,9 +458,14 @@ public class OpenIdProvider extends ContainerLifeCycle
         }
 
         public String getIdToken(String provider, String clientId, long duration)
+        {
+            return getIdToken(provider, clientId, duration, null);
+        }
+
+        public String getIdToken(String provider, String clientId, long duration, String sid)
         {
             long expiryTime = Instant.now().plusMillis(duration).getEpochSecond();
-            return JwtEncoder.createIdToken(provider, clientId, subject, name, expiryTime);
+            return JwtEncoder.createIdToken(provider, clientId, subject, name, expiryTime, sid);
         }
 
         @Override
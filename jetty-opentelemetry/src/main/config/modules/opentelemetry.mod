@@ -0,0 +1,32 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Creates OpenTelemetry tracing spans for requests.
+The OpenTelemetry instance is obtained from GlobalOpenTelemetry,
+so an OpenTelemetry SDK must be registered, for example by adding
+the SDK autoconfigure jars to the lib/opentelemetry directory.
+
+[tags]
+server
+
+[depend]
+server
+
+[files]
+maven://io.opentelemetry/opentelemetry-api/${opentelemetry.version}|lib/opentelemetry/opentelemetry-api-${opentelemetry.version}.jar
+maven://io.opentelemetry/opentelemetry-context/${opentelemetry.version}|lib/opentelemetry/opentelemetry-context-${opentelemetry.version}.jar
+
+[xml]
+etc/jetty-opentelemetry.xml
+
+[lib]
+lib/jetty-opentelemetry-${jetty.version}.jar
+lib/opentelemetry/*.jar
+
+[ini]
+opentelemetry.version?=@opentelemetry.version@
+
+[license]
+OpenTelemetry is an open source project hosted on Github and released under the Apache 2.0 license.
+https://github.com/open-telemetry/opentelemetry-java
+http://www.apache.org/licenses/LICENSE-2.0.html
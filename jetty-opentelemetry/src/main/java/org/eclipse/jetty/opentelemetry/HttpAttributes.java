@@ -0,0 +1,60 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.opentelemetry;
+
+import io.opentelemetry.api.common.AttributeKey;
+
+/**
+ * <p>The HTTP semantic convention attribute keys used by this module,
+ * defined here to avoid a dependency on the incubating semantic
+ * conventions artifact.</p>
+ */
+final class HttpAttributes
+{
+    static final AttributeKey<String> HTTP_REQUEST_METHOD = AttributeKey.stringKey("http.request.method");
+    static final AttributeKey<Long> HTTP_REQUEST_RESEND_COUNT = AttributeKey.longKey("http.request.resend_count");
+    static final AttributeKey<Long> HTTP_RESPONSE_STATUS_CODE = AttributeKey.longKey("http.response.status_code");
+    static final AttributeKey<String> URL_FULL = AttributeKey.stringKey("url.full");
+    static final AttributeKey<String> URL_SCHEME = AttributeKey.stringKey("url.scheme");
+    static final AttributeKey<String> URL_PATH = AttributeKey.stringKey("url.path");
+    static final AttributeKey<String> URL_QUERY = AttributeKey.stringKey("url.query");
+    static final AttributeKey<String> SERVER_ADDRESS = AttributeKey.stringKey("server.address");
+    static final AttributeKey<Long> SERVER_PORT = AttributeKey.longKey("server.port");
+    static final AttributeKey<String> CLIENT_ADDRESS = AttributeKey.stringKey("client.address");
+    static final AttributeKey<Long> CLIENT_PORT = AttributeKey.longKey("client.port");
+    static final AttributeKey<String> NETWORK_PROTOCOL_NAME = AttributeKey.stringKey("network.protocol.name");
+    static final AttributeKey<String> NETWORK_PROTOCOL_VERSION = AttributeKey.stringKey("network.protocol.version");
+    static final AttributeKey<String> USER_AGENT_ORIGINAL = AttributeKey.stringKey("user_agent.original");
+    static final AttributeKey<String> ERROR_TYPE = AttributeKey.stringKey("error.type");
+
+    private HttpAttributes()
+    {
+    }
+
+    /**
+     * @param version the HTTP version, for example {@code HTTP/1.1}
+     * @return the version without the {@code HTTP/} prefix, for example {@code 1.1} or {@code 2}
+     */
+    static String protocolVersion(String version)
+    {
+        if (version == null)
+            return null;
+        int slash = version.indexOf('/');
+        String result = slash < 0 ? version : version.substring(slash + 1);
+        // HTTP/2 and HTTP/3 have no minor version.
+        if (result.endsWith(".0") && !result.startsWith("1"))
+            result = result.substring(0, result.length() - 2);
+        return result;
+    }
+}
@@ -0,0 +1,246 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.opentelemetry;
+
+import java.io.IOException;
+import java.util.Collections;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.RequestDispatcher;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import io.opentelemetry.api.GlobalOpenTelemetry;
+import io.opentelemetry.api.OpenTelemetry;
+import io.opentelemetry.api.trace.Span;
+import io.opentelemetry.api.trace.SpanBuilder;
+import io.opentelemetry.api.trace.SpanKind;
+import io.opentelemetry.api.trace.StatusCode;
+import io.opentelemetry.api.trace.Tracer;
+import io.opentelemetry.context.Context;
+import io.opentelemetry.context.Scope;
+import io.opentelemetry.context.propagation.TextMapGetter;
+import io.opentelemetry.context.propagation.TextMapPropagator;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.server.HttpChannel;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A handler that creates an OpenTelemetry {@link SpanKind#SERVER SERVER} span for each request.</p>
+ * <p>The span context of the caller is extracted from the request headers with the
+ * configured propagators, by default the W3C {@code traceparent} and {@code tracestate}
+ * headers, and the span is made current while the request is handled by the wrapped
+ * handlers, so that spans created by the application are children of the server span.</p>
+ * <p>The span has the HTTP semantic convention attributes, and it is ended when the
+ * request and response processing is complete, also for asynchronous requests.</p>
+ * <p>The span, and the {@link Context} containing it, are available to the application
+ * as request attributes named {@link #SPAN_ATTRIBUTE} and {@link #CONTEXT_ATTRIBUTE},
+ * for example to make the context current in threads handling asynchronous requests.</p>
+ */
+@ManagedObject("OpenTelemetry tracing handler")
+public class OpenTelemetryHandler extends HandlerWrapper
+{
+    public static final String INSTRUMENTATION_NAME = "org.eclipse.jetty.opentelemetry";
+    public static final String SPAN_ATTRIBUTE = Span.class.getName();
+    public static final String CONTEXT_ATTRIBUTE = Context.class.getName();
+
+    private static final Logger LOG = LoggerFactory.getLogger(OpenTelemetryHandler.class);
+    private static final TextMapGetter<HttpServletRequest> GETTER = new TextMapGetter<>()
+    {
+        @Override
+        public Iterable<String> keys(HttpServletRequest request)
+        {
+            return Collections.list(request.getHeaderNames());
+        }
+
+        @Override
+        public String get(HttpServletRequest request, String key)
+        {
+            return request == null ? null : request.getHeader(key);
+        }
+    };
+
+    private final OpenTelemetry openTelemetry;
+    private final Tracer tracer;
+    private final TextMapPropagator propagator;
+
+    /**
+     * <p>Creates a handler that uses the {@link GlobalOpenTelemetry} instance.</p>
+     */
+    public OpenTelemetryHandler()
+    {
+        this(GlobalOpenTelemetry.get());
+    }
+
+    public OpenTelemetryHandler(OpenTelemetry openTelemetry)
+    {
+        this.openTelemetry = Objects.requireNonNull(openTelemetry);
+        this.tracer = openTelemetry.getTracer(INSTRUMENTATION_NAME);
+        this.propagator = openTelemetry.getPropagators().getTextMapPropagator();
+    }
+
+    public OpenTelemetry getOpenTelemetry()
+    {
+        return openTelemetry;
+    }
+
+    @ManagedAttribute("The propagated fields")
+    public String getPropagatedFields()
+    {
+        return String.join(",", propagator.fields());
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        HttpChannel.TransientListeners.addTo(getServer());
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // Asynchronous dispatches, error dispatches or nested
+        // handlers reuse the span created for the request.
+        Object existing = baseRequest.getAttribute(CONTEXT_ATTRIBUTE);
+        if (existing instanceof Context)
+        {
+            try (Scope ignored = ((Context)existing).makeCurrent())
+            {
+                super.handle(target, baseRequest, request, response);
+            }
+            return;
+        }
+
+        Context parent = propagator.extract(Context.root(), request, GETTER);
+        Span span = newSpan(parent, baseRequest);
+        Context context = parent.with(span);
+        baseRequest.setAttribute(SPAN_ATTRIBUTE, span);
+        baseRequest.setAttribute(CONTEXT_ATTRIBUTE, context);
+        baseRequest.getHttpChannel().addListener(new SpanListener(span));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Started {} for {}", span, baseRequest);
+
+        try (Scope ignored = context.makeCurrent())
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        catch (IOException | ServletException | RuntimeException | Error x)
+        {
+            span.recordException(x);
+            throw x;
+        }
+    }
+
+    /**
+     * <p>Creates and starts the server span for the given request.</p>
+     * <p>Subclasses may override this method to add attributes to the span.</p>
+     *
+     * @param parent the parent context, extracted from the request headers
+     * @param request the request
+     * @return the started span
+     */
+    protected Span newSpan(Context parent, Request request)
+    {
+        SpanBuilder builder = tracer.spanBuilder(getSpanName(request))
+            .setParent(parent)
+            .setSpanKind(SpanKind.SERVER)
+            .setStartTimestamp(request.getTimeStamp(), TimeUnit.MILLISECONDS)
+            .setAttribute(HttpAttributes.HTTP_REQUEST_METHOD, request.getMethod())
+            .setAttribute(HttpAttributes.URL_SCHEME, request.getScheme())
+            .setAttribute(HttpAttributes.URL_PATH, request.getRequestURI())
+            .setAttribute(HttpAttributes.SERVER_ADDRESS, request.getServerName())
+            .setAttribute(HttpAttributes.SERVER_PORT, (long)request.getServerPort())
+            .setAttribute(HttpAttributes.CLIENT_ADDRESS, request.getRemoteAddr())
+            .setAttribute(HttpAttributes.CLIENT_PORT, (long)request.getRemotePort())
+            .setAttribute(HttpAttributes.NETWORK_PROTOCOL_NAME, "http")
+            .setAttribute(HttpAttributes.NETWORK_PROTOCOL_VERSION, HttpAttributes.protocolVersion(request.getProtocol()));
+        String query = request.getQueryString();
+        if (query != null)
+            builder.setAttribute(HttpAttributes.URL_QUERY, query);
+        String userAgent = request.getHeader(HttpHeader.USER_AGENT.asString());
+        if (userAgent != null)
+            builder.setAttribute(HttpAttributes.USER_AGENT_ORIGINAL, userAgent);
+        return builder.startSpan();
+    }
+
+    /**
+     * @param request the request
+     * @return the name of the span, by default the request method
+     */
+    protected String getSpanName(Request request)
+    {
+        return request.getMethod();
+    }
+
+    /**
+     * <p>Ends the server span for the given request.</p>
+     *
+     * @param span the span to end
+     * @param request the completed request
+     */
+    protected void endSpan(Span span, Request request)
+    {
+        int status = request.getResponse().getStatus();
+        span.setAttribute(HttpAttributes.HTTP_RESPONSE_STATUS_CODE, status);
+        Object failure = request.getAttribute(RequestDispatcher.ERROR_EXCEPTION);
+        if (failure instanceof Throwable)
+            span.recordException((Throwable)failure);
+        // Only 5xx responses are server errors.
+        if (status >= 500)
+        {
+            span.setAttribute(HttpAttributes.ERROR_TYPE, Integer.toString(status));
+            span.setStatus(StatusCode.ERROR);
+        }
+        span.end();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Ended {} for {}", span, request);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), tracer);
+    }
+
+    private class SpanListener implements HttpChannel.Listener
+    {
+        private final Span span;
+
+        private SpanListener(Span span)
+        {
+            this.span = span;
+        }
+
+        @Override
+        public void onResponseFailure(Request request, Throwable failure)
+        {
+            span.recordException(failure);
+            span.setAttribute(HttpAttributes.ERROR_TYPE, failure.getClass().getName());
+            span.setStatus(StatusCode.ERROR);
+        }
+
+        @Override
+        public void onComplete(Request request)
+        {
+            endSpan(span, request);
+        }
+    }
+}
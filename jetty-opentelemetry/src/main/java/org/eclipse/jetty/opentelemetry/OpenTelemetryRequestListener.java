@@ -0,0 +1,217 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.opentelemetry;
+
+import java.util.Iterator;
+import java.util.Objects;
+
+import io.opentelemetry.api.GlobalOpenTelemetry;
+import io.opentelemetry.api.OpenTelemetry;
+import io.opentelemetry.api.common.Attributes;
+import io.opentelemetry.api.trace.Span;
+import io.opentelemetry.api.trace.SpanKind;
+import io.opentelemetry.api.trace.StatusCode;
+import io.opentelemetry.api.trace.Tracer;
+import io.opentelemetry.context.Context;
+import io.opentelemetry.context.propagation.TextMapPropagator;
+import io.opentelemetry.context.propagation.TextMapSetter;
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpConversation;
+import org.eclipse.jetty.client.HttpExchange;
+import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link Request.Listener} that creates an OpenTelemetry {@link SpanKind#CLIENT CLIENT}
+ * span for each request sent by an {@link HttpClient}, and propagates the span context
+ * to the server in the request headers, by default with the W3C {@code traceparent} header.</p>
+ * <p>Typical usage is:</p>
+ * <pre>
+ * HttpClient httpClient = new HttpClient();
+ * httpClient.getRequestListeners().add(new OpenTelemetryRequestListener(openTelemetry));
+ * </pre>
+ * <p>The span is a child of the context that is current when the request is sent.
+ * Requests that are resent within the same conversation, for example to follow
+ * a redirect or to authenticate, have their own span, that is a child of the span
+ * of the first request; the span of the first request ends when the whole conversation
+ * completes, and records an event for each redirect or resend.</p>
+ * <p>The request lifecycle events, such as the request commit that for HTTP/2
+ * corresponds to sending the {@code HEADERS} frame of the stream, are recorded
+ * as span events.</p>
+ */
+public class OpenTelemetryRequestListener implements Request.Listener
+{
+    public static final String SPAN_ATTRIBUTE = OpenTelemetryRequestListener.class.getName() + ".span";
+
+    private static final Logger LOG = LoggerFactory.getLogger(OpenTelemetryRequestListener.class);
+    private static final TextMapSetter<Request> SETTER = (request, key, value) ->
+    {
+        if (request != null)
+            request.headers(headers -> headers.put(key, value));
+    };
+
+    private final Tracer tracer;
+    private final TextMapPropagator propagator;
+
+    /**
+     * <p>Creates a listener that uses the {@link GlobalOpenTelemetry} instance.</p>
+     */
+    public OpenTelemetryRequestListener()
+    {
+        this(GlobalOpenTelemetry.get());
+    }
+
+    public OpenTelemetryRequestListener(OpenTelemetry openTelemetry)
+    {
+        Objects.requireNonNull(openTelemetry);
+        this.tracer = openTelemetry.getTracer(OpenTelemetryHandler.INSTRUMENTATION_NAME);
+        this.propagator = openTelemetry.getPropagators().getTextMapPropagator();
+    }
+
+    @Override
+    public void onQueued(Request request)
+    {
+        HttpConversation conversation = request instanceof HttpRequest ? ((HttpRequest)request).getConversation() : null;
+        Span first = conversation == null ? null : (Span)conversation.getAttribute(SPAN_ATTRIBUTE);
+        Context parent = first == null ? Context.current() : Context.current().with(first);
+
+        Span span = tracer.spanBuilder(request.getMethod())
+            .setParent(parent)
+            .setSpanKind(SpanKind.CLIENT)
+            .setAttribute(HttpAttributes.HTTP_REQUEST_METHOD, request.getMethod())
+            .setAttribute(HttpAttributes.URL_FULL, request.getURI().toString())
+            .setAttribute(HttpAttributes.SERVER_ADDRESS, request.getHost())
+            .setAttribute(HttpAttributes.SERVER_PORT, (long)request.getPort())
+            .startSpan();
+
+        if (first == null)
+        {
+            if (conversation != null)
+                conversation.setAttribute(SPAN_ATTRIBUTE, span);
+        }
+        else
+        {
+            int resends = conversation.getExchanges().size() - 1;
+            span.setAttribute(HttpAttributes.HTTP_REQUEST_RESEND_COUNT, resends);
+            first.addEvent(resendEventName(conversation), Attributes.of(
+                HttpAttributes.URL_FULL, request.getURI().toString(),
+                HttpAttributes.HTTP_REQUEST_RESEND_COUNT, (long)resends));
+        }
+
+        request.attribute(SPAN_ATTRIBUTE, span);
+        propagator.inject(parent.with(span), request, SETTER);
+        request.onComplete(result -> endSpan(span, result));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Started {} for {}", span, request);
+    }
+
+    private String resendEventName(HttpConversation conversation)
+    {
+        // The last exchange is the one of the request being resent.
+        Iterator<HttpExchange> exchanges = conversation.getExchanges().descendingIterator();
+        exchanges.next();
+        if (!exchanges.hasNext())
+            return "http.resend";
+        int status = exchanges.next().getResponse().getStatus();
+        if (HttpStatus.isRedirection(status))
+            return "http.redirect";
+        if (status == HttpStatus.UNAUTHORIZED_401 || status == HttpStatus.PROXY_AUTHENTICATION_REQUIRED_407)
+            return "http.authenticate";
+        return "http.resend";
+    }
+
+    @Override
+    public void onBegin(Request request)
+    {
+        addEvent(request, "http.request.begin");
+    }
+
+    @Override
+    public void onCommit(Request request)
+    {
+        addEvent(request, "http.request.commit");
+    }
+
+    @Override
+    public void onSuccess(Request request)
+    {
+        addEvent(request, "http.request.sent");
+    }
+
+    @Override
+    public void onFailure(Request request, Throwable failure)
+    {
+        Span span = getSpan(request);
+        if (span != null)
+            span.recordException(failure);
+    }
+
+    private void addEvent(Request request, String name)
+    {
+        Span span = getSpan(request);
+        if (span != null)
+            span.addEvent(name);
+    }
+
+    private static Span getSpan(Request request)
+    {
+        Object span = request.getAttributes().get(SPAN_ATTRIBUTE);
+        return span instanceof Span ? (Span)span : null;
+    }
+
+    /**
+     * <p>Ends the client span for the given result.</p>
+     *
+     * @param span the span to end
+     * @param result the request/response result
+     */
+    protected void endSpan(Span span, Result result)
+    {
+        Response response = result.getResponse();
+        int status = response == null ? 0 : response.getStatus();
+        if (status > 0)
+        {
+            span.setAttribute(HttpAttributes.HTTP_RESPONSE_STATUS_CODE, status);
+            HttpVersion version = response.getVersion();
+            if (version != null)
+            {
+                span.setAttribute(HttpAttributes.NETWORK_PROTOCOL_NAME, "http");
+                span.setAttribute(HttpAttributes.NETWORK_PROTOCOL_VERSION, HttpAttributes.protocolVersion(version.asString()));
+            }
+        }
+
+        Throwable failure = result.getFailure();
+        if (failure != null)
+        {
+            span.recordException(failure);
+            span.setAttribute(HttpAttributes.ERROR_TYPE, failure.getClass().getName());
+            span.setStatus(StatusCode.ERROR);
+        }
+        else if (status >= 400)
+        {
+            // For clients, 4xx responses are errors too.
+            span.setAttribute(HttpAttributes.ERROR_TYPE, Integer.toString(status));
+            span.setStatus(StatusCode.ERROR);
+        }
+        span.end();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Ended {} for {}", span, result);
+    }
+}
@@ -0,0 +1,166 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.opentelemetry;
+
+import java.util.List;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import io.opentelemetry.api.trace.Span;
+import io.opentelemetry.api.trace.SpanKind;
+import io.opentelemetry.api.trace.StatusCode;
+import io.opentelemetry.api.trace.propagation.W3CTraceContextPropagator;
+import io.opentelemetry.context.propagation.ContextPropagators;
+import io.opentelemetry.sdk.OpenTelemetrySdk;
+import io.opentelemetry.sdk.testing.exporter.InMemorySpanExporter;
+import io.opentelemetry.sdk.trace.SdkTracerProvider;
+import io.opentelemetry.sdk.trace.data.SpanData;
+import io.opentelemetry.sdk.trace.export.SimpleSpanProcessor;
+import org.awaitility.Awaitility;
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class OpenTelemetryHandlerTest
+{
+    private static final String TRACE_ID = "4bf92f3577b34da6a3ce929d0e0e4736";
+    private static final String PARENT_SPAN_ID = "00f067aa0ba902b7";
+
+    private final InMemorySpanExporter exporter = InMemorySpanExporter.create();
+    private OpenTelemetrySdk openTelemetry;
+    private Server server;
+    private LocalConnector localConnector;
+    private ServerConnector connector;
+    private HttpClient client;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        openTelemetry = OpenTelemetrySdk.builder()
+            .setTracerProvider(SdkTracerProvider.builder().addSpanProcessor(SimpleSpanProcessor.create(exporter)).build())
+            .setPropagators(ContextPropagators.create(W3CTraceContextPropagator.getInstance()))
+            .build();
+
+        server = new Server();
+        localConnector = new LocalConnector(server);
+        server.addConnector(localConnector);
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+        OpenTelemetryHandler handler = new OpenTelemetryHandler(openTelemetry);
+        handler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                // The server span is current while handling.
+                assertThat(Span.current().getSpanContext().isValid(), is(true));
+                assertThat(request.getAttribute(OpenTelemetryHandler.SPAN_ATTRIBUTE), notNullValue());
+                response.setStatus("/fail".equals(target) ? HttpStatus.SERVICE_UNAVAILABLE_503 : HttpStatus.OK_200);
+            }
+        });
+        server.setHandler(handler);
+        server.start();
+
+        client = new HttpClient();
+        client.getRequestListeners().add(new OpenTelemetryRequestListener(openTelemetry));
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (server != null)
+            server.stop();
+        if (openTelemetry != null)
+            openTelemetry.close();
+    }
+
+    private List<SpanData> awaitSpans(int count)
+    {
+        Awaitility.await().atMost(5, TimeUnit.SECONDS).until(() -> exporter.getFinishedSpanItems().size() >= count);
+        return exporter.getFinishedSpanItems();
+    }
+
+    @Test
+    public void testServerSpanHonorsTraceParent() throws Exception
+    {
+        HttpTester.Response response = HttpTester.parseResponse(localConnector.getResponse(
+            "GET /path?a=b HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "traceparent: 00-" + TRACE_ID + "-" + PARENT_SPAN_ID + "-01\r\n" +
+            "Connection: close\r\n" +
+            "\r\n"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        List<SpanData> spans = awaitSpans(1);
+        assertThat(spans, hasSize(1));
+        SpanData span = spans.get(0);
+        assertThat(span.getKind(), is(SpanKind.SERVER));
+        assertThat(span.getName(), is("GET"));
+        assertThat(span.getTraceId(), is(TRACE_ID));
+        assertThat(span.getParentSpanId(), is(PARENT_SPAN_ID));
+        assertThat(span.getAttributes().get(HttpAttributes.HTTP_REQUEST_METHOD), is("GET"));
+        assertThat(span.getAttributes().get(HttpAttributes.URL_PATH), is("/path"));
+        assertThat(span.getAttributes().get(HttpAttributes.URL_QUERY), is("a=b"));
+        assertThat(span.getAttributes().get(HttpAttributes.NETWORK_PROTOCOL_VERSION), is("1.1"));
+        assertThat(span.getAttributes().get(HttpAttributes.HTTP_RESPONSE_STATUS_CODE), is(200L));
+        assertThat(span.getStatus().getStatusCode(), is(StatusCode.UNSET));
+    }
+
+    @Test
+    public void testServerErrorSpan() throws Exception
+    {
+        HttpTester.Response response = HttpTester.parseResponse(localConnector.getResponse(
+            "GET /fail HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"));
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+
+        SpanData span = awaitSpans(1).get(0);
+        assertThat(span.getParentSpanContext().isValid(), is(false));
+        assertThat(span.getAttributes().get(HttpAttributes.ERROR_TYPE), is("503"));
+        assertThat(span.getStatus().getStatusCode(), is(StatusCode.ERROR));
+    }
+
+    @Test
+    public void testClientSpanPropagatesToServer() throws Exception
+    {
+        ContentResponse response = client.GET("http://localhost:" + connector.getLocalPort() + "/path");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        List<SpanData> spans = awaitSpans(2);
+        SpanData clientSpan = spans.stream().filter(s -> s.getKind() == SpanKind.CLIENT).findFirst().orElseThrow();
+        SpanData serverSpan = spans.stream().filter(s -> s.getKind() == SpanKind.SERVER).findFirst().orElseThrow();
+        assertThat(serverSpan.getTraceId(), is(clientSpan.getTraceId()));
+        assertThat(serverSpan.getParentSpanId(), is(clientSpan.getSpanId()));
+        assertThat(clientSpan.getAttributes().get(HttpAttributes.HTTP_RESPONSE_STATUS_CODE), is(200L));
+        assertThat(clientSpan.getEvents().stream().anyMatch(e -> e.getName().equals("http.request.commit")), is(true));
+    }
+}
@@ -0,0 +1,40 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Puts a pipeline of handlers, assembled from the declarative YAML or JSON
+configuration file etc/pipeline.yaml, in front of the server handlers.
+The pipeline stages (gzip, cors, qos, rateLimit, rewrite and contexts with
+static resources and proxy routes) and their order are configured in the file,
+which is reloaded when it changes without interrupting the requests in progress.
+
+[tags]
+server
+handler
+
+[depend]
+server
+servlet
+client
+
+[xml]
+etc/jetty-pipeline.xml
+
+[files]
+basehome:modules/pipeline/pipeline.yaml|etc/pipeline.yaml
+
+[lib]
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/jetty-rewrite-${jetty.version}.jar
+lib/jetty-proxy-${jetty.version}.jar
+lib/jetty-servlets-${jetty.version}.jar
+lib/jetty-pipeline-${jetty.version}.jar
+
+[ini-template]
+## The pipeline configuration file.
+# jetty.pipeline.config=etc/pipeline.yaml
+
+## Whether the pipeline configuration file is reloaded when it changes.
+# jetty.pipeline.hotReload=true
+
+## The max time, in milliseconds, a replaced pipeline waits for its requests to complete.
+# jetty.pipeline.drainTimeout=30000
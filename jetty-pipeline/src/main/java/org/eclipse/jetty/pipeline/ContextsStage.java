@@ -0,0 +1,180 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.io.IOException;
+import java.net.URI;
+import java.util.List;
+import java.util.Set;
+
+import org.eclipse.jetty.proxy.ProxyServlet;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.ContextHandlerCollection;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.server.handler.ResourceHandler;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.servlet.ServletHolder;
+import org.eclipse.jetty.util.resource.Resource;
+
+/**
+ * <p>The {@code contexts} stage, that dispatches the requests to contexts by context path
+ * and virtual host with a {@link ContextHandlerCollection}; it must be the last stage.</p>
+ * <p>The stage has a {@code contexts} array, whose elements have a required
+ * {@code contextPath}, an optional {@code virtualHosts} array, an optional nested
+ * {@code pipeline} array of stages that only apply to the context, and either:</p>
+ * <ul>
+ * <li>a {@code resourceBase} directory of static resources to serve, with the optional
+ * {@code dirAllowed}, {@code welcomeFiles} and {@code cacheControl} members; or</li>
+ * <li>a {@code routes} array of proxy routes, whose elements have a {@code path} servlet
+ * path spec, by default {@code /*}, and the {@link ProxyServlet.Transparent} parameters
+ * {@code proxyTo}, required, {@code prefix}, {@code preserveHost}, {@code timeout},
+ * {@code idleTimeout} and {@code maxConnections}.</li>
+ * </ul>
+ */
+class ContextsStage implements PipelineStage
+{
+    private static final Set<String> MEMBERS = Set.of("type", "contexts");
+    private static final Set<String> CONTEXT_MEMBERS = Set.of("contextPath", "virtualHosts", "pipeline", "resourceBase", "dirAllowed", "welcomeFiles", "cacheControl", "routes");
+    private static final Set<String> ROUTE_MEMBERS = Set.of("path", "proxyTo", "prefix", "preserveHost", "timeout", "idleTimeout", "maxConnections");
+
+    @Override
+    public String getType()
+    {
+        return "contexts";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        List<PipelineNode> nodes = node.getNodes("contexts");
+        if (nodes.isEmpty())
+            throw node.invalid("contexts", "a non-empty array", node.has("contexts") ? List.of() : null);
+        ContextHandlerCollection contexts = new ContextHandlerCollection();
+        for (PipelineNode context : nodes)
+        {
+            contexts.addHandler(newContext(context, builder));
+        }
+        return contexts;
+    }
+
+    private ContextHandler newContext(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(CONTEXT_MEMBERS);
+        String contextPath = node.getString("contextPath");
+        if (!contextPath.startsWith("/"))
+            throw node.invalid("contextPath", "a path starting with '/'", contextPath);
+
+        boolean resources = node.has("resourceBase");
+        if (resources == node.has("routes"))
+            throw new IllegalArgumentException("Invalid " + node.getPath() + ": expected either resourceBase or routes");
+
+        ContextHandler context = resources ? newResourceContext(node) : newProxyContext(node);
+        context.setContextPath(contextPath);
+        List<String> virtualHosts = node.getStrings("virtualHosts");
+        if (!virtualHosts.isEmpty())
+            context.setVirtualHosts(virtualHosts.toArray(String[]::new));
+
+        HandlerWrapper pipeline = builder.buildWrappers(node.getNodes("pipeline"));
+        if (pipeline != null)
+            context.insertHandler(pipeline);
+        return context;
+    }
+
+    private ContextHandler newResourceContext(PipelineNode node)
+    {
+        String resourceBase = node.getString("resourceBase");
+        Resource baseResource;
+        try
+        {
+            baseResource = Resource.newResource(resourceBase);
+        }
+        catch (IOException x)
+        {
+            throw node.invalid("resourceBase", "a directory", resourceBase);
+        }
+        if (!baseResource.exists() || !baseResource.isDirectory())
+            throw node.invalid("resourceBase", "an existing directory", resourceBase);
+
+        ResourceHandler resourceHandler = new ResourceHandler();
+        resourceHandler.setBaseResource(baseResource);
+        resourceHandler.setDirAllowed(node.getBoolean("dirAllowed", false));
+        List<String> welcomeFiles = node.getStrings("welcomeFiles");
+        if (!welcomeFiles.isEmpty())
+            resourceHandler.setWelcomeFiles(welcomeFiles.toArray(String[]::new));
+        resourceHandler.setCacheControl(node.getString("cacheControl", null));
+
+        ContextHandler context = new ContextHandler();
+        context.setHandler(resourceHandler);
+        return context;
+    }
+
+    private ContextHandler newProxyContext(PipelineNode node)
+    {
+        for (String name : Set.of("dirAllowed", "welcomeFiles", "cacheControl"))
+        {
+            if (node.has(name))
+                throw new IllegalArgumentException("Unknown member " + node.getPath() + "." + name + " in a context with routes");
+        }
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.NO_SESSIONS);
+        List<PipelineNode> routes = node.getNodes("routes");
+        if (routes.isEmpty())
+            throw node.invalid("routes", "a non-empty array", List.of());
+        for (PipelineNode route : routes)
+        {
+            route.checkMembers(ROUTE_MEMBERS);
+            ServletHolder holder = new ServletHolder(ProxyServlet.Transparent.class);
+            holder.setInitParameter("proxyTo", proxyTo(route));
+            String prefix = route.getString("prefix", null);
+            if (prefix != null)
+            {
+                if (!prefix.startsWith("/"))
+                    throw route.invalid("prefix", "a path starting with '/'", prefix);
+                holder.setInitParameter("prefix", prefix);
+            }
+            if (route.has("preserveHost"))
+                holder.setInitParameter("preserveHost", String.valueOf(route.getBoolean("preserveHost", false)));
+            for (String name : List.of("timeout", "idleTimeout", "maxConnections"))
+            {
+                if (route.has(name))
+                {
+                    long value = route.getLong(name, 0);
+                    if (value < 0)
+                        throw route.invalid(name, "a non-negative integer", value);
+                    holder.setInitParameter(name, String.valueOf(value));
+                }
+            }
+            context.addServlet(holder, route.getString("path", "/*"));
+        }
+        return context;
+    }
+
+    private static String proxyTo(PipelineNode route)
+    {
+        String proxyTo = route.getString("proxyTo");
+        try
+        {
+            URI uri = URI.create(proxyTo);
+            String scheme = uri.getScheme();
+            if (("http".equalsIgnoreCase(scheme) || "https".equalsIgnoreCase(scheme)) && uri.getHost() != null)
+                return proxyTo;
+        }
+        catch (IllegalArgumentException x)
+        {
+            // Fall through and report the invalid value.
+        }
+        throw route.invalid("proxyTo", "an absolute http or https URI", proxyTo);
+    }
+}
@@ -0,0 +1,177 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.io.IOException;
+import java.util.Collections;
+import java.util.Enumeration;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.atomic.AtomicBoolean;
+import javax.servlet.FilterConfig;
+import javax.servlet.ServletContext;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.servlets.CrossOriginFilter;
+
+/**
+ * <p>The {@code cors} stage, that implements cross-origin resource sharing
+ * with a {@link CrossOriginFilter}.</p>
+ * <p>The members of the stage are the {@link CrossOriginFilter} parameters:
+ * the arrays {@code allowedOrigins}, {@code allowedTimingOrigins}, {@code allowedMethods},
+ * {@code allowedHeaders} and {@code exposedHeaders}, and {@code preflightMaxAge},
+ * {@code allowCredentials}, {@code chainPreflight}, {@code allowPrivateNetwork}
+ * and {@code preflightCacheControl}.</p>
+ */
+class CrossOriginStage implements PipelineStage
+{
+    private static final Set<String> LIST_MEMBERS = Set.of(
+        CrossOriginFilter.ALLOWED_ORIGINS_PARAM,
+        CrossOriginFilter.ALLOWED_TIMING_ORIGINS_PARAM,
+        CrossOriginFilter.ALLOWED_METHODS_PARAM,
+        CrossOriginFilter.ALLOWED_HEADERS_PARAM,
+        CrossOriginFilter.EXPOSED_HEADERS_PARAM);
+    private static final Set<String> BOOLEAN_MEMBERS = Set.of(
+        CrossOriginFilter.ALLOW_CREDENTIALS_PARAM,
+        CrossOriginFilter.CHAIN_PREFLIGHT_PARAM,
+        CrossOriginFilter.ALLOW_PRIVATE_NETWORK_PARAM);
+    private static final Set<String> MEMBERS = Set.of("type",
+        CrossOriginFilter.ALLOWED_ORIGINS_PARAM,
+        CrossOriginFilter.ALLOWED_TIMING_ORIGINS_PARAM,
+        CrossOriginFilter.ALLOWED_METHODS_PARAM,
+        CrossOriginFilter.ALLOWED_HEADERS_PARAM,
+        CrossOriginFilter.EXPOSED_HEADERS_PARAM,
+        CrossOriginFilter.ALLOW_CREDENTIALS_PARAM,
+        CrossOriginFilter.CHAIN_PREFLIGHT_PARAM,
+        CrossOriginFilter.ALLOW_PRIVATE_NETWORK_PARAM,
+        CrossOriginFilter.PREFLIGHT_MAX_AGE_PARAM,
+        CrossOriginFilter.PREFLIGHT_CACHE_CONTROL_PARAM);
+
+    @Override
+    public String getType()
+    {
+        return "cors";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        Map<String, String> parameters = new HashMap<>();
+        for (String name : LIST_MEMBERS)
+        {
+            List<String> values = node.getStrings(name);
+            if (node.has(name))
+                parameters.put(name, String.join(",", values));
+        }
+        for (String name : BOOLEAN_MEMBERS)
+        {
+            if (node.has(name))
+                parameters.put(name, String.valueOf(node.getBoolean(name, false)));
+        }
+        if (node.has(CrossOriginFilter.PREFLIGHT_MAX_AGE_PARAM))
+        {
+            int maxAge = node.getInt(CrossOriginFilter.PREFLIGHT_MAX_AGE_PARAM, 0);
+            if (maxAge < 0)
+                throw node.invalid(CrossOriginFilter.PREFLIGHT_MAX_AGE_PARAM, "a non-negative integer", maxAge);
+            parameters.put(CrossOriginFilter.PREFLIGHT_MAX_AGE_PARAM, String.valueOf(maxAge));
+        }
+        String cacheControl = node.getString(CrossOriginFilter.PREFLIGHT_CACHE_CONTROL_PARAM, null);
+        if (cacheControl != null)
+            parameters.put(CrossOriginFilter.PREFLIGHT_CACHE_CONTROL_PARAM, cacheControl);
+        return new CrossOriginHandler(parameters);
+    }
+
+    /**
+     * <p>A {@link HandlerWrapper} that applies a {@link CrossOriginFilter} to the requests,
+     * so that the filter can be used outside of a servlet context.</p>
+     */
+    static class CrossOriginHandler extends HandlerWrapper
+    {
+        private final CrossOriginFilter filter = new CrossOriginFilter();
+        private final Map<String, String> parameters;
+
+        CrossOriginHandler(Map<String, String> parameters)
+        {
+            this.parameters = Map.copyOf(parameters);
+        }
+
+        @Override
+        protected void doStart() throws Exception
+        {
+            filter.init(new FilterConfig()
+            {
+                @Override
+                public String getFilterName()
+                {
+                    return "cors";
+                }
+
+                @Override
+                public ServletContext getServletContext()
+                {
+                    // Not used by CrossOriginFilter.
+                    return null;
+                }
+
+                @Override
+                public String getInitParameter(String name)
+                {
+                    return parameters.get(name);
+                }
+
+                @Override
+                public Enumeration<String> getInitParameterNames()
+                {
+                    return Collections.enumeration(parameters.keySet());
+                }
+            });
+            super.doStart();
+        }
+
+        @Override
+        protected void doStop() throws Exception
+        {
+            super.doStop();
+            filter.destroy();
+        }
+
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+        {
+            AtomicBoolean chained = new AtomicBoolean();
+            filter.doFilter(request, response, (req, res) ->
+            {
+                chained.set(true);
+                super.handle(target, baseRequest, (HttpServletRequest)req, (HttpServletResponse)res);
+            });
+            // The filter responded to a preflight request without chaining.
+            if (!chained.get())
+                baseRequest.setHandled(true);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), parameters);
+        }
+    }
+}
@@ -0,0 +1,73 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.List;
+import java.util.Set;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.gzip.GzipHandler;
+
+/**
+ * <p>The {@code gzip} stage, that compresses the responses and inflates the requests
+ * with a {@link GzipHandler}.</p>
+ * <p>The members of the stage are {@code minGzipSize}, {@code inflateBufferSize},
+ * {@code syncFlush} and the arrays {@code includedMimeTypes}, {@code excludedMimeTypes},
+ * {@code includedPaths}, {@code excludedPaths}, {@code includedMethods} and
+ * {@code excludedMethods}, with the semantic of the correspondent {@link GzipHandler}
+ * properties.</p>
+ */
+class GzipStage implements PipelineStage
+{
+    private static final Set<String> MEMBERS = Set.of("type", "minGzipSize", "inflateBufferSize", "syncFlush",
+        "includedMimeTypes", "excludedMimeTypes", "includedPaths", "excludedPaths", "includedMethods", "excludedMethods");
+
+    @Override
+    public String getType()
+    {
+        return "gzip";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        GzipHandler gzipHandler = new GzipHandler();
+        int minGzipSize = node.getInt("minGzipSize", gzipHandler.getMinGzipSize());
+        if (minGzipSize < 0)
+            throw node.invalid("minGzipSize", "a non-negative integer", minGzipSize);
+        gzipHandler.setMinGzipSize(minGzipSize);
+        gzipHandler.setInflateBufferSize(node.getInt("inflateBufferSize", gzipHandler.getInflateBufferSize()));
+        gzipHandler.setSyncFlush(node.getBoolean("syncFlush", gzipHandler.isSyncFlush()));
+        List<String> values = node.getStrings("includedMimeTypes");
+        if (!values.isEmpty())
+            gzipHandler.setIncludedMimeTypes(values.toArray(String[]::new));
+        values = node.getStrings("excludedMimeTypes");
+        if (!values.isEmpty())
+            gzipHandler.setExcludedMimeTypes(values.toArray(String[]::new));
+        values = node.getStrings("includedPaths");
+        if (!values.isEmpty())
+            gzipHandler.setIncludedPaths(values.toArray(String[]::new));
+        values = node.getStrings("excludedPaths");
+        if (!values.isEmpty())
+            gzipHandler.setExcludedPaths(values.toArray(String[]::new));
+        values = node.getStrings("includedMethods");
+        if (!values.isEmpty())
+            gzipHandler.setIncludedMethods(values.toArray(String[]::new));
+        values = node.getStrings("excludedMethods");
+        if (!values.isEmpty())
+            gzipHandler.setExcludedMethods(values.toArray(String[]::new));
+        return gzipHandler;
+    }
+}
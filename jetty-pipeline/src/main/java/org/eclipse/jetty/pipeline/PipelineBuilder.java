@@ -0,0 +1,256 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.TreeSet;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.HandlerList;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.ajax.YamlParser;
+
+/**
+ * <p>Builds a {@link Handler} tree from a pipeline configuration document.</p>
+ * <p>The document is an object whose {@code pipeline} member is the ordered array of
+ * the stages of the pipeline; each stage wraps the stages that follow it, so that the
+ * first stage sees the requests first and the responses last. For example, in YAML:</p>
+ * <pre>
+ * pipeline:
+ *   - type: gzip
+ *     minGzipSize: 1024
+ *   - type: cors
+ *     allowedOrigins: ["https://*.example.com"]
+ *   - type: qos
+ *     maxConcurrency: 200
+ *     maxQueueSize: 1000
+ *   - type: rateLimit
+ *     rate: 50
+ *     key: "header:X-API-Key"
+ *   - type: rewrite
+ *     rules:
+ *       - type: redirect
+ *         pattern: /old/*
+ *         location: /new
+ *   - type: contexts
+ *     contexts:
+ *       - contextPath: /static
+ *         resourceBase: /var/www/static
+ *       - contextPath: /api
+ *         pipeline:
+ *           - type: rateLimit
+ *             rate: 10
+ *         routes:
+ *           - path: /v1/*
+ *             proxyTo: http://backend:8080/
+ * </pre>
+ * <p>The built-in stage types are:</p>
+ * <ul>
+ * <li>{@code gzip}: a {@link org.eclipse.jetty.server.handler.gzip.GzipHandler};</li>
+ * <li>{@code cors}: cross-origin resource sharing, with the parameters of
+ * {@link org.eclipse.jetty.servlets.CrossOriginFilter};</li>
+ * <li>{@code qos}: a {@link org.eclipse.jetty.server.handler.BulkheadHandler} that limits
+ * the concurrency of the requests and queues the requests in excess;</li>
+ * <li>{@code rateLimit}: a {@link org.eclipse.jetty.server.handler.RateLimitHandler};</li>
+ * <li>{@code rewrite}: a {@link org.eclipse.jetty.rewrite.handler.RewriteHandler} with
+ * rewrite, redirect, header and response rules;</li>
+ * <li>{@code contexts}: the contexts that serve static resources or proxy routes,
+ * each with an optional nested pipeline; this stage must be the last stage.</li>
+ * </ul>
+ * <p>Other stage types can be added with {@link #addStage(PipelineStage)}.</p>
+ * <p>The document is validated while the handlers are built: unknown stage types,
+ * unknown members and members of the wrong type are reported as
+ * {@link IllegalArgumentException}s whose message contains the path of the offending
+ * member, such as {@code pipeline[0].minGzipSize}.</p>
+ */
+public class PipelineBuilder
+{
+    private final Map<String, PipelineStage> stages = new LinkedHashMap<>();
+
+    public PipelineBuilder()
+    {
+        addStage(new GzipStage());
+        addStage(new CrossOriginStage());
+        addStage(new QoSStage());
+        addStage(new RateLimitStage());
+        addStage(new RewriteStage());
+        addStage(new ContextsStage());
+    }
+
+    /**
+     * <p>Adds a stage type, replacing the stage type with the same name, if any.</p>
+     *
+     * @param stage the stage type to add
+     */
+    public void addStage(PipelineStage stage)
+    {
+        stages.put(Objects.requireNonNull(stage.getType()), stage);
+    }
+
+    /**
+     * @param type the stage type name
+     * @return the stage type with the given name, or null if there is no such stage type
+     */
+    public PipelineStage getStage(String type)
+    {
+        return stages.get(type);
+    }
+
+    /**
+     * @return the names of the stage types
+     */
+    public Set<String> getStageTypes()
+    {
+        return new TreeSet<>(stages.keySet());
+    }
+
+    /**
+     * <p>Parses a pipeline configuration document.</p>
+     *
+     * @param content the document content
+     * @param yaml whether the document is YAML, rather than JSON
+     * @return the document object
+     * @throws IllegalArgumentException if the document cannot be parsed or is not an object
+     * @see YamlParser
+     */
+    public static Map<?, ?> parse(String content, boolean yaml)
+    {
+        Object parsed;
+        if (yaml)
+        {
+            parsed = YamlParser.parse(content);
+        }
+        else
+        {
+            try
+            {
+                parsed = new JSON().fromJSON(content);
+            }
+            catch (RuntimeException x)
+            {
+                throw new IllegalArgumentException("Invalid JSON pipeline configuration", x);
+            }
+        }
+        if (!(parsed instanceof Map))
+            throw new IllegalArgumentException("Invalid pipeline configuration: expected an object");
+        return (Map<?, ?>)parsed;
+    }
+
+    /**
+     * <p>Builds the handler of a pipeline configuration document.</p>
+     *
+     * @param document the document object
+     * @param next the handler that the pipeline forwards to after its last stage, or null
+     * @return the handler of the first stage of the pipeline, or {@code next} if the pipeline is empty
+     * @throws IllegalArgumentException if the document is invalid
+     * @see #parse(String, boolean)
+     */
+    public Handler build(Map<?, ?> document, Handler next)
+    {
+        PipelineNode root = new PipelineNode("", document);
+        root.checkMembers(Set.of("pipeline"));
+        if (!root.has("pipeline"))
+            throw new IllegalArgumentException("Missing pipeline");
+        return build(root.getNodes("pipeline"), next);
+    }
+
+    /**
+     * <p>Builds the handlers of the given stages, in order.</p>
+     * <p>The last stage forwards to {@code next}: if the last stage is a {@link HandlerWrapper},
+     * {@code next} is its wrapped handler, otherwise {@code next} handles the requests
+     * that the last stage did not handle.</p>
+     *
+     * @param nodes the stages configuration
+     * @param next the handler that the pipeline forwards to after its last stage, or null
+     * @return the handler of the first stage, or {@code next} if there are no stages
+     * @throws IllegalArgumentException if a stage configuration is invalid
+     */
+    public Handler build(List<PipelineNode> nodes, Handler next)
+    {
+        List<Handler> handlers = newHandlers(nodes);
+        if (handlers.isEmpty())
+            return next;
+        for (int i = 0; i < handlers.size() - 1; ++i)
+        {
+            if (!(handlers.get(i) instanceof HandlerWrapper))
+                throw new IllegalArgumentException("Invalid " + nodes.get(i).getPath() + ": stage " + nodes.get(i).getString("type") + " must be the last stage");
+        }
+        Handler last = handlers.get(handlers.size() - 1);
+        if (last instanceof HandlerWrapper)
+            ((HandlerWrapper)last).setHandler(next);
+        else if (next != null)
+            handlers.set(handlers.size() - 1, new HandlerList(last, next));
+        link(handlers);
+        return handlers.get(0);
+    }
+
+    /**
+     * <p>Builds the handlers of the given stages, that must all be {@link HandlerWrapper}s,
+     * as a chain of wrappers suitable for {@link HandlerWrapper#insertHandler(HandlerWrapper)}.</p>
+     *
+     * @param nodes the stages configuration
+     * @return the first wrapper of the chain, or null if there are no stages
+     * @throws IllegalArgumentException if a stage configuration is invalid or a stage is not a wrapper
+     */
+    public HandlerWrapper buildWrappers(List<PipelineNode> nodes)
+    {
+        List<Handler> handlers = newHandlers(nodes);
+        if (handlers.isEmpty())
+            return null;
+        for (int i = 0; i < handlers.size(); ++i)
+        {
+            if (!(handlers.get(i) instanceof HandlerWrapper))
+                throw new IllegalArgumentException("Invalid " + nodes.get(i).getPath() + ": stage " + nodes.get(i).getString("type") + " cannot be nested");
+        }
+        link(handlers);
+        return (HandlerWrapper)handlers.get(0);
+    }
+
+    private List<Handler> newHandlers(List<PipelineNode> nodes)
+    {
+        List<Handler> handlers = new ArrayList<>();
+        for (PipelineNode node : nodes)
+        {
+            String type = node.getString("type");
+            PipelineStage stage = stages.get(type);
+            if (stage == null)
+                throw node.invalid("type", "one of " + getStageTypes(), type);
+            Handler handler = stage.newHandler(node, this);
+            if (handler == null)
+                throw new IllegalStateException("No handler for stage " + type + " at " + node.getPath());
+            handlers.add(handler);
+        }
+        return handlers;
+    }
+
+    private static void link(List<Handler> handlers)
+    {
+        for (int i = 0; i < handlers.size() - 1; ++i)
+        {
+            ((HandlerWrapper)handlers.get(i)).setHandler(handlers.get(i + 1));
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), getStageTypes());
+    }
+}
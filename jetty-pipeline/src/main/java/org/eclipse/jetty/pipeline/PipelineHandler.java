@@ -0,0 +1,499 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.PathWatcher;
+import org.eclipse.jetty.util.PathWatcher.PathWatchEvent;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that handles the requests with a pipeline of handlers
+ * assembled from a declarative YAML or JSON configuration file.</p>
+ * <p>The configuration file format is described in {@link PipelineBuilder}; it is YAML
+ * if the file name ends with {@code .yaml} or {@code .yml}, JSON if it ends with
+ * {@code .json}, and otherwise JSON if its content starts with <code>{</code>.</p>
+ * <p>The last stage of the pipeline forwards to the handler wrapped by this handler,
+ * so that, for example, the server contexts can be put behind a pipeline of gzip,
+ * CORS and rate limiting stages; if the last stage is the {@code contexts} stage,
+ * the requests that match no context are forwarded to the wrapped handler.</p>
+ * <p>The configuration can be {@link #reload() reloaded}, or automatically reloaded
+ * when the file changes if {@link #setHotReload(boolean) hot reload} is enabled.
+ * Reloading builds and starts a new pipeline, that handles the new requests, while
+ * the requests in progress complete in the previous pipeline, which is stopped when
+ * its requests are completed or when the {@link #setDrainTimeout(long) drain timeout}
+ * expires, whichever comes first.
+ * If the new configuration is invalid or its handlers fail to start, the previous
+ * pipeline is retained.</p>
+ */
+@ManagedObject("Configuration driven handler pipeline")
+public class PipelineHandler extends HandlerWrapper implements PathWatcher.Listener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PipelineHandler.class);
+    private static final String GENERATION_ATTRIBUTE = PipelineHandler.class.getName() + ".generation";
+
+    private final AutoLock lock = new AutoLock();
+    private final Set<Generation> draining = ConcurrentHashMap.newKeySet();
+    private final Scheduler scheduler = new ScheduledExecutorScheduler(String.format("Pipeline-Scheduler-%x", hashCode()), false);
+    private final PipelineBuilder builder;
+    private Path configPath;
+    private boolean hotReload;
+    private long drainTimeout = 30000;
+    private PathWatcher pathWatcher;
+    private long generations;
+    private volatile Generation generation;
+
+    public PipelineHandler()
+    {
+        this(new PipelineBuilder());
+    }
+
+    /**
+     * @param builder the builder of the pipeline, possibly with additional stage types
+     */
+    public PipelineHandler(PipelineBuilder builder)
+    {
+        this.builder = builder;
+        addBean(scheduler);
+    }
+
+    /**
+     * @return the builder of the pipeline
+     */
+    public PipelineBuilder getPipelineBuilder()
+    {
+        return builder;
+    }
+
+    /**
+     * @return the configuration file path as a string
+     */
+    @ManagedAttribute("The pipeline configuration file")
+    public String getConfig()
+    {
+        return configPath == null ? null : configPath.toString();
+    }
+
+    /**
+     * @param config the configuration file path as a string
+     */
+    public void setConfig(String config)
+    {
+        setConfigPath(config == null ? null : Path.of(config));
+    }
+
+    /**
+     * @return the configuration file path
+     */
+    public Path getConfigPath()
+    {
+        return configPath;
+    }
+
+    /**
+     * @param configPath the configuration file path
+     */
+    public void setConfigPath(Path configPath)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        this.configPath = configPath;
+    }
+
+    /**
+     * @return whether the configuration file is reloaded when it changes
+     */
+    @ManagedAttribute("Whether the configuration file is reloaded when it changes")
+    public boolean isHotReload()
+    {
+        return hotReload;
+    }
+
+    /**
+     * @param hotReload whether the configuration file is reloaded when it changes
+     */
+    public void setHotReload(boolean hotReload)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        this.hotReload = hotReload;
+    }
+
+    /**
+     * @return the max time in milliseconds that a replaced pipeline waits for its requests
+     * to complete before being stopped, or 0 to wait forever
+     */
+    @ManagedAttribute("The max time in milliseconds a replaced pipeline waits for its requests to complete")
+    public long getDrainTimeout()
+    {
+        return drainTimeout;
+    }
+
+    /**
+     * @param drainTimeout the max time in milliseconds that a replaced pipeline waits for its
+     * requests to complete before being stopped, or 0 to wait forever
+     */
+    public void setDrainTimeout(long drainTimeout)
+    {
+        this.drainTimeout = drainTimeout;
+    }
+
+    /**
+     * @return the number of the current pipeline, incremented at every successful reload,
+     * or 0 if no pipeline has been loaded
+     */
+    @ManagedAttribute("The number of the current pipeline, incremented at every successful reload")
+    public long getGeneration()
+    {
+        Generation current = generation;
+        return current == null ? 0 : current.number;
+    }
+
+    /**
+     * @return the handler of the current pipeline, or null if no pipeline has been loaded
+     */
+    public Handler getPipeline()
+    {
+        Generation current = generation;
+        return current == null ? null : current.handler;
+    }
+
+    /**
+     * @return the number of replaced pipelines that are waiting for their requests to complete
+     */
+    @ManagedAttribute("The number of replaced pipelines waiting for their requests to complete")
+    public int getDrainingPipelines()
+    {
+        return draining.size();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        super.doStart();
+        if (configPath != null)
+        {
+            reload();
+            if (isHotReload())
+            {
+                pathWatcher = new PathWatcher();
+                pathWatcher.watch(configPath);
+                pathWatcher.addListener(this);
+                pathWatcher.setNotifyExistingOnStart(false);
+                pathWatcher.start();
+            }
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        if (pathWatcher != null)
+            pathWatcher.stop();
+        pathWatcher = null;
+        // The pipelines are managed beans, stopped by super.doStop().
+        super.doStop();
+        try (AutoLock l = lock.lock())
+        {
+            Generation current = generation;
+            generation = null;
+            if (current != null)
+                current.stop();
+            for (Generation drained : draining)
+            {
+                drained.stop();
+            }
+        }
+    }
+
+    /**
+     * <p>Reloads the configuration file, replacing the current pipeline.</p>
+     *
+     * @throws IOException if the configuration file cannot be read
+     * @throws IllegalArgumentException if the configuration file is invalid
+     * @throws Exception if the handlers of the new pipeline fail to start
+     */
+    @ManagedOperation(value = "Reloads the pipeline configuration file", impact = "ACTION")
+    public void reload() throws Exception
+    {
+        if (configPath == null)
+            return;
+        String content = Files.readString(configPath, StandardCharsets.UTF_8);
+        Map<?, ?> document = PipelineBuilder.parse(content, isYaml(configPath, content));
+        try (AutoLock l = lock.lock())
+        {
+            Handler handler = builder.build(document, new NextHandler());
+            handler.setServer(getServer());
+            try
+            {
+                addBean(handler, true);
+                if (isRunning() && !handler.isStarted())
+                    handler.start();
+            }
+            catch (Throwable x)
+            {
+                removeBean(handler);
+                stopQuietly(handler);
+                throw x;
+            }
+            Generation previous = generation;
+            generation = new Generation(++generations, handler);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Loaded pipeline #{} from {}: {}", generations, configPath, handler);
+            if (previous != null)
+                previous.retire();
+        }
+    }
+
+    private static boolean isYaml(Path path, String content)
+    {
+        String name = path.getFileName().toString().toLowerCase(Locale.ENGLISH);
+        if (name.endsWith(".yaml") || name.endsWith(".yml"))
+            return true;
+        if (name.endsWith(".json"))
+            return false;
+        return !content.strip().startsWith("{");
+    }
+
+    @Override
+    public void onPathWatchEvent(PathWatchEvent event)
+    {
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Path watch event: {}", event.getType());
+            reload();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to reload pipeline configuration {}, retaining the previous pipeline", configPath, x);
+        }
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // An asynchronous dispatch is handled by the pipeline that handled the initial dispatch.
+        Generation generation = (Generation)baseRequest.getAttribute(GENERATION_ATTRIBUTE);
+        if (generation != null)
+        {
+            HandlerInstrumentation.handle(generation.handler, target, baseRequest, request, response);
+            return;
+        }
+
+        generation = acquire();
+        if (generation == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setAttribute(GENERATION_ATTRIBUTE, generation);
+        try
+        {
+            HandlerInstrumentation.handle(generation.handler, target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (request.isAsyncStarted())
+                request.getAsyncContext().addListener(new Completion(generation));
+            else
+                generation.release();
+        }
+    }
+
+    private Generation acquire()
+    {
+        while (true)
+        {
+            Generation current = generation;
+            // A pipeline that has been replaced and has drained cannot be acquired,
+            // but by then the current pipeline has already been updated.
+            if (current == null || current.acquire())
+                return current;
+        }
+    }
+
+    private static void stopQuietly(Handler handler)
+    {
+        try
+        {
+            handler.stop();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to stop {}", handler, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[config=%s,generation=%d,draining=%d]", getClass().getSimpleName(), hashCode(), configPath, getGeneration(), getDrainingPipelines());
+    }
+
+    /**
+     * <p>The last handler of a pipeline, that forwards to the handler wrapped by this handler.</p>
+     */
+    private class NextHandler extends AbstractHandler
+    {
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+        {
+            PipelineHandler.super.handle(target, baseRequest, request, response);
+        }
+    }
+
+    /**
+     * <p>A pipeline, with a reference count of its requests plus one while it is the current pipeline.</p>
+     */
+    private class Generation
+    {
+        private final AtomicInteger references = new AtomicInteger(1);
+        private final AtomicBoolean stopped = new AtomicBoolean();
+        private final long number;
+        private final Handler handler;
+        private volatile Scheduler.Task timeout;
+
+        private Generation(long number, Handler handler)
+        {
+            this.number = number;
+            this.handler = handler;
+        }
+
+        private boolean acquire()
+        {
+            while (true)
+            {
+                int count = references.get();
+                if (count == 0)
+                    return false;
+                if (references.compareAndSet(count, count + 1))
+                    return true;
+            }
+        }
+
+        private void release()
+        {
+            if (references.decrementAndGet() == 0)
+            {
+                // Do not stop the pipeline from the thread of one of its requests.
+                if (isRunning())
+                    scheduler.schedule(this::stop, 0, TimeUnit.MILLISECONDS);
+                else
+                    stop();
+            }
+        }
+
+        private void retire()
+        {
+            draining.add(this);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Draining pipeline #{} with {} requests", number, references.get() - 1);
+            long drainTimeout = getDrainTimeout();
+            if (drainTimeout > 0)
+                timeout = scheduler.schedule(this::expire, drainTimeout, TimeUnit.MILLISECONDS);
+            release();
+        }
+
+        private void expire()
+        {
+            if (stopped.get())
+                return;
+            LOG.warn("Stopping pipeline #{} with {} requests after drain timeout {} ms", number, references.get(), getDrainTimeout());
+            stop();
+        }
+
+        private void stop()
+        {
+            if (!stopped.compareAndSet(false, true))
+                return;
+            Scheduler.Task task = timeout;
+            if (task != null)
+                task.cancel();
+            draining.remove(this);
+            stopQuietly(handler);
+            removeBean(handler);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Stopped pipeline #{}", number);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[#%d,references=%d]", getClass().getSimpleName(), hashCode(), number, references.get());
+        }
+    }
+
+    private static class Completion implements AsyncListener
+    {
+        private final Generation generation;
+
+        private Completion(Generation generation)
+        {
+            this.generation = generation;
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            generation.release();
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            // Listeners are cleared when the request is suspended again.
+            event.getAsyncContext().addListener(this);
+        }
+    }
+}
@@ -0,0 +1,307 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Collections;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.TreeSet;
+
+/**
+ * <p>An object of a pipeline configuration document, with accessors that validate
+ * the type of its members.</p>
+ * <p>Validation failures are reported as {@link IllegalArgumentException}s whose message
+ * contains the path of the offending member within the document, for example
+ * {@code pipeline[2].contexts[0].routes[1].proxyTo}.</p>
+ */
+public class PipelineNode
+{
+    private final String path;
+    private final Map<?, ?> members;
+
+    /**
+     * @param path the path of this object within the document, empty for the document root
+     * @param members the members of this object
+     */
+    public PipelineNode(String path, Map<?, ?> members)
+    {
+        this.path = path;
+        this.members = members;
+    }
+
+    /**
+     * @return the path of this object within the document
+     */
+    public String getPath()
+    {
+        return path;
+    }
+
+    /**
+     * @param name the member name
+     * @return whether this object has the given member with a non-null value
+     */
+    public boolean has(String name)
+    {
+        return members.get(name) != null;
+    }
+
+    /**
+     * <p>Validates that this object only has members with the given names.</p>
+     *
+     * @param names the allowed member names
+     * @throws IllegalArgumentException if this object has other members
+     */
+    public void checkMembers(Set<String> names)
+    {
+        for (Object name : members.keySet())
+        {
+            if (!names.contains(String.valueOf(name)))
+                throw new IllegalArgumentException("Unknown member " + path(name) + ", expected one of " + new TreeSet<>(names));
+        }
+    }
+
+    /**
+     * @param name the member name
+     * @return the string value of the member
+     * @throws IllegalArgumentException if the member is missing or is not a string
+     */
+    public String getString(String name)
+    {
+        String value = getString(name, null);
+        if (value == null)
+            throw new IllegalArgumentException("Missing " + path(name));
+        return value;
+    }
+
+    /**
+     * @param name the member name
+     * @param defaultValue the value to return if the member is missing
+     * @return the string value of the member
+     * @throws IllegalArgumentException if the member is not a string
+     */
+    public String getString(String name, String defaultValue)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return defaultValue;
+        if (value instanceof Map || value instanceof List || value instanceof Object[])
+            throw invalid(name, "a string", value);
+        return value.toString();
+    }
+
+    /**
+     * @param name the member name
+     * @param defaultValue the value to return if the member is missing
+     * @return the integer value of the member
+     * @throws IllegalArgumentException if the member is not an integer
+     */
+    public int getInt(String name, int defaultValue)
+    {
+        long value = getLong(name, defaultValue);
+        if (value < Integer.MIN_VALUE || value > Integer.MAX_VALUE)
+            throw invalid(name, "an integer", value);
+        return (int)value;
+    }
+
+    /**
+     * @param name the member name
+     * @param defaultValue the value to return if the member is missing
+     * @return the long value of the member
+     * @throws IllegalArgumentException if the member is not an integer
+     */
+    public long getLong(String name, long defaultValue)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return defaultValue;
+        if (value instanceof Long || value instanceof Integer)
+            return ((Number)value).longValue();
+        if (value instanceof String)
+        {
+            try
+            {
+                return Long.parseLong((String)value);
+            }
+            catch (NumberFormatException x)
+            {
+                // Fall through and report the invalid value.
+            }
+        }
+        throw invalid(name, "an integer", value);
+    }
+
+    /**
+     * @param name the member name
+     * @param defaultValue the value to return if the member is missing
+     * @return the numeric value of the member
+     * @throws IllegalArgumentException if the member is not a number
+     */
+    public double getDouble(String name, double defaultValue)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return defaultValue;
+        if (value instanceof Number)
+            return ((Number)value).doubleValue();
+        if (value instanceof String)
+        {
+            try
+            {
+                return Double.parseDouble((String)value);
+            }
+            catch (NumberFormatException x)
+            {
+                // Fall through and report the invalid value.
+            }
+        }
+        throw invalid(name, "a number", value);
+    }
+
+    /**
+     * @param name the member name
+     * @param defaultValue the value to return if the member is missing
+     * @return the boolean value of the member
+     * @throws IllegalArgumentException if the member is not a boolean
+     */
+    public boolean getBoolean(String name, boolean defaultValue)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return defaultValue;
+        if (value instanceof Boolean)
+            return (Boolean)value;
+        if ("true".equals(value))
+            return true;
+        if ("false".equals(value))
+            return false;
+        throw invalid(name, "a boolean", value);
+    }
+
+    /**
+     * <p>Returns the values of a member that is either an array of strings or a single string.</p>
+     *
+     * @param name the member name
+     * @return the string values of the member, or an empty list if the member is missing
+     * @throws IllegalArgumentException if the member is not a string or an array of strings
+     */
+    public List<String> getStrings(String name)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return List.of();
+        if (value instanceof Map)
+            throw invalid(name, "an array of strings", value);
+        List<?> values = asList(value);
+        if (values == null)
+            return List.of(value.toString());
+        List<String> result = new ArrayList<>();
+        for (Object element : values)
+        {
+            if (element == null || element instanceof Map || asList(element) != null)
+                throw invalid(name + "[" + result.size() + "]", "a string", element);
+            result.add(element.toString());
+        }
+        return Collections.unmodifiableList(result);
+    }
+
+    /**
+     * @param name the member name
+     * @return the object value of the member, or null if the member is missing
+     * @throws IllegalArgumentException if the member is not an object
+     */
+    public PipelineNode getNode(String name)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return null;
+        if (!(value instanceof Map))
+            throw invalid(name, "an object", value);
+        return new PipelineNode(path(name), (Map<?, ?>)value);
+    }
+
+    /**
+     * @param name the member name
+     * @return the object values of a member that is an array of objects, or an empty list if the member is missing
+     * @throws IllegalArgumentException if the member is not an array of objects
+     */
+    public List<PipelineNode> getNodes(String name)
+    {
+        Object value = members.get(name);
+        if (value == null)
+            return List.of();
+        List<?> values = asList(value);
+        if (values == null)
+            throw invalid(name, "an array of objects", value);
+        List<PipelineNode> result = new ArrayList<>();
+        for (Object element : values)
+        {
+            String elementPath = path(name) + "[" + result.size() + "]";
+            if (!(element instanceof Map))
+                throw new IllegalArgumentException("Invalid " + elementPath + ": expected an object, found " + describe(element));
+            result.add(new PipelineNode(elementPath, (Map<?, ?>)element));
+        }
+        return Collections.unmodifiableList(result);
+    }
+
+    /**
+     * <p>Creates the exception reporting an invalid member value.</p>
+     *
+     * @param name the member name
+     * @param expected the description of the expected value
+     * @param value the invalid value
+     * @return the exception to throw
+     */
+    public IllegalArgumentException invalid(String name, String expected, Object value)
+    {
+        return new IllegalArgumentException("Invalid " + path(name) + ": expected " + expected + ", found " + describe(value));
+    }
+
+    private String path(Object name)
+    {
+        return path.isEmpty() ? String.valueOf(name) : path + "." + name;
+    }
+
+    private static List<?> asList(Object value)
+    {
+        // JSON arrays are parsed as Object[], YAML sequences as List.
+        if (value instanceof List)
+            return (List<?>)value;
+        if (value instanceof Object[])
+            return Arrays.asList((Object[])value);
+        return null;
+    }
+
+    private static String describe(Object value)
+    {
+        if (value == null)
+            return "null";
+        if (value instanceof Map)
+            return "an object";
+        if (asList(value) != null)
+            return "an array";
+        if (value instanceof String)
+            return "'" + value + "'";
+        return String.valueOf(value);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), path);
+    }
+}
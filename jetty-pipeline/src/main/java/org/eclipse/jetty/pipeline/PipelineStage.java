@@ -0,0 +1,48 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+
+/**
+ * <p>A type of stage of a pipeline, that creates the {@link Handler} of a stage
+ * from its configuration.</p>
+ * <p>Stages are declared in the pipeline configuration document as objects whose
+ * {@code type} member is the {@link #getType() type} of the stage, for example
+ * {@code {"type": "gzip", "minGzipSize": 1024}}.</p>
+ * <p>A stage that returns a {@link HandlerWrapper} wraps the stages that follow it
+ * in the pipeline; a stage that returns any other handler must be the last stage.</p>
+ *
+ * @see PipelineBuilder#addStage(PipelineStage)
+ */
+public interface PipelineStage
+{
+    /**
+     * @return the type of this stage, as referenced by the {@code type} member of the stage configuration
+     */
+    String getType();
+
+    /**
+     * <p>Creates the handler of a stage.</p>
+     * <p>Implementations must validate the stage configuration, including rejecting
+     * unknown members with {@link PipelineNode#checkMembers(java.util.Set)}.</p>
+     *
+     * @param node the stage configuration
+     * @param builder the builder, that may be used to build nested pipelines
+     * @return the handler of the stage
+     * @throws IllegalArgumentException if the stage configuration is invalid
+     */
+    Handler newHandler(PipelineNode node, PipelineBuilder builder);
+}
@@ -0,0 +1,71 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.Set;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.handler.BulkheadHandler;
+
+/**
+ * <p>The {@code qos} stage, that limits the number of requests handled concurrently
+ * with the default partition of a {@link BulkheadHandler}.</p>
+ * <p>The members of the stage are:</p>
+ * <ul>
+ * <li>{@code maxConcurrency}: the max number of requests handled concurrently, required;</li>
+ * <li>{@code maxQueueSize}: the max number of requests waiting to be handled, by default 0;</li>
+ * <li>{@code queueTimeout}: the max time in milliseconds a request waits, by default 0 to wait forever;</li>
+ * <li>{@code rejectStatus}: the response status of the rejected requests, by default 503;</li>
+ * <li>{@code retryAfter}: the {@code Retry-After} seconds of the rejected requests,
+ * or a negative value for no {@code Retry-After} header.</li>
+ * </ul>
+ */
+class QoSStage implements PipelineStage
+{
+    private static final Set<String> MEMBERS = Set.of("type", "maxConcurrency", "maxQueueSize", "queueTimeout", "rejectStatus", "retryAfter");
+
+    @Override
+    public String getType()
+    {
+        return "qos";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        if (!node.has("maxConcurrency"))
+            throw new IllegalArgumentException("Missing " + node.getPath() + ".maxConcurrency");
+        BulkheadHandler bulkheadHandler = new BulkheadHandler();
+        BulkheadHandler.Partition partition = bulkheadHandler.getDefaultPartition();
+        int maxConcurrency = node.getInt("maxConcurrency", 0);
+        if (maxConcurrency <= 0)
+            throw node.invalid("maxConcurrency", "a positive integer", maxConcurrency);
+        partition.setMaxConcurrency(maxConcurrency);
+        int maxQueueSize = node.getInt("maxQueueSize", 0);
+        if (maxQueueSize < 0)
+            throw node.invalid("maxQueueSize", "a non-negative integer", maxQueueSize);
+        partition.setMaxQueueSize(maxQueueSize);
+        long queueTimeout = node.getLong("queueTimeout", 0);
+        if (queueTimeout < 0)
+            throw node.invalid("queueTimeout", "a non-negative integer", queueTimeout);
+        partition.setQueueTimeout(queueTimeout);
+        int rejectStatus = node.getInt("rejectStatus", bulkheadHandler.getRejectStatus());
+        if (rejectStatus < 400 || rejectStatus > 599)
+            throw node.invalid("rejectStatus", "an error status code", rejectStatus);
+        bulkheadHandler.setRejectStatus(rejectStatus);
+        bulkheadHandler.setRetryAfter(node.getInt("retryAfter", bulkheadHandler.getRetryAfter()));
+        return bulkheadHandler;
+    }
+}
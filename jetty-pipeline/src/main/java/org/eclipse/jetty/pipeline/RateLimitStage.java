@@ -0,0 +1,94 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.Locale;
+import java.util.Set;
+import java.util.function.Function;
+
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.RateLimitHandler;
+
+/**
+ * <p>The {@code rateLimit} stage, that limits the request rate per key
+ * with a {@link RateLimitHandler}.</p>
+ * <p>The members of the stage are:</p>
+ * <ul>
+ * <li>{@code rate}: the number of requests per second allowed for each key;</li>
+ * <li>{@code burst}: the max number of requests in a burst, or waiting with the leaky bucket
+ * algorithm, for each key;</li>
+ * <li>{@code algorithm}: either {@code token_bucket} (the default), that rejects the requests
+ * in excess, or {@code leaky_bucket}, that delays them;</li>
+ * <li>{@code maxKeys}: the max number of keys tracked;</li>
+ * <li>{@code key}: either {@code remoteAddress} (the default), {@code path}
+ * or {@code header:<name>} for the value of the given request header.</li>
+ * </ul>
+ */
+class RateLimitStage implements PipelineStage
+{
+    private static final Set<String> MEMBERS = Set.of("type", "rate", "burst", "algorithm", "maxKeys", "key");
+    private static final String HEADER_KEY_PREFIX = "header:";
+
+    @Override
+    public String getType()
+    {
+        return "rateLimit";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        RateLimitHandler rateLimitHandler = new RateLimitHandler();
+        double rate = node.getDouble("rate", rateLimitHandler.getRate());
+        if (!(rate > 0))
+            throw node.invalid("rate", "a positive number", rate);
+        rateLimitHandler.setRate(rate);
+        int burst = node.getInt("burst", rateLimitHandler.getBurst());
+        if (burst <= 0)
+            throw node.invalid("burst", "a positive integer", burst);
+        rateLimitHandler.setBurst(burst);
+        int maxKeys = node.getInt("maxKeys", rateLimitHandler.getMaxKeys());
+        if (maxKeys <= 0)
+            throw node.invalid("maxKeys", "a positive integer", maxKeys);
+        rateLimitHandler.setMaxKeys(maxKeys);
+        String algorithm = node.getString("algorithm", null);
+        if (algorithm != null)
+        {
+            try
+            {
+                rateLimitHandler.setAlgorithm(RateLimitHandler.Algorithm.valueOf(algorithm.toUpperCase(Locale.ENGLISH)));
+            }
+            catch (IllegalArgumentException x)
+            {
+                throw node.invalid("algorithm", "token_bucket or leaky_bucket", algorithm);
+            }
+        }
+        rateLimitHandler.setKeyExtractor(newKeyExtractor(node));
+        return rateLimitHandler;
+    }
+
+    private static Function<Request, String> newKeyExtractor(PipelineNode node)
+    {
+        String key = node.getString("key", "remoteAddress");
+        if ("remoteAddress".equals(key))
+            return RateLimitHandler.remoteAddress();
+        if ("path".equals(key))
+            return RateLimitHandler.path();
+        if (key.startsWith(HEADER_KEY_PREFIX) && key.length() > HEADER_KEY_PREFIX.length())
+            return RateLimitHandler.header(key.substring(HEADER_KEY_PREFIX.length()).trim());
+        throw node.invalid("key", "remoteAddress, path or header:<name>", key);
+    }
+}
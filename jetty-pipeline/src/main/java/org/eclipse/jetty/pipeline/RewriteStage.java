@@ -0,0 +1,161 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.util.Set;
+import java.util.regex.Pattern;
+import java.util.regex.PatternSyntaxException;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.rewrite.handler.HeaderPatternRule;
+import org.eclipse.jetty.rewrite.handler.HeaderRegexRule;
+import org.eclipse.jetty.rewrite.handler.RedirectPatternRule;
+import org.eclipse.jetty.rewrite.handler.RedirectRegexRule;
+import org.eclipse.jetty.rewrite.handler.ResponsePatternRule;
+import org.eclipse.jetty.rewrite.handler.RewriteHandler;
+import org.eclipse.jetty.rewrite.handler.RewritePatternRule;
+import org.eclipse.jetty.rewrite.handler.RewriteRegexRule;
+import org.eclipse.jetty.rewrite.handler.Rule;
+import org.eclipse.jetty.server.Handler;
+
+/**
+ * <p>The {@code rewrite} stage, that applies rules to the requests with a {@link RewriteHandler}.</p>
+ * <p>The members of the stage are {@code rewriteRequestURI}, {@code rewritePathInfo},
+ * {@code originalPathAttribute} and the {@code rules} array, whose elements have a
+ * {@code type} member and an optional {@code terminating} member:</p>
+ * <ul>
+ * <li>{@code rewrite}: a {@link RewritePatternRule} with {@code pattern} and {@code replacement};</li>
+ * <li>{@code rewriteRegex}: a {@link RewriteRegexRule} with {@code regex} and {@code replacement};</li>
+ * <li>{@code redirect}: a {@link RedirectPatternRule} with {@code pattern}, {@code location}
+ * and {@code statusCode};</li>
+ * <li>{@code redirectRegex}: a {@link RedirectRegexRule} with {@code regex}, {@code location}
+ * and {@code statusCode};</li>
+ * <li>{@code header}: a {@link HeaderPatternRule} with {@code pattern}, {@code name},
+ * {@code value} and {@code add};</li>
+ * <li>{@code headerRegex}: a {@link HeaderRegexRule} with {@code regex}, {@code name},
+ * {@code value} and {@code add};</li>
+ * <li>{@code response}: a {@link ResponsePatternRule} with {@code pattern}, {@code code}
+ * and {@code message}.</li>
+ * </ul>
+ */
+class RewriteStage implements PipelineStage
+{
+    private static final Set<String> MEMBERS = Set.of("type", "rewriteRequestURI", "rewritePathInfo", "originalPathAttribute", "rules");
+
+    @Override
+    public String getType()
+    {
+        return "rewrite";
+    }
+
+    @Override
+    public Handler newHandler(PipelineNode node, PipelineBuilder builder)
+    {
+        node.checkMembers(MEMBERS);
+        RewriteHandler rewriteHandler = new RewriteHandler();
+        rewriteHandler.setRewriteRequestURI(node.getBoolean("rewriteRequestURI", rewriteHandler.isRewriteRequestURI()));
+        rewriteHandler.setRewritePathInfo(node.getBoolean("rewritePathInfo", rewriteHandler.isRewritePathInfo()));
+        rewriteHandler.setOriginalPathAttribute(node.getString("originalPathAttribute", rewriteHandler.getOriginalPathAttribute()));
+        for (PipelineNode rule : node.getNodes("rules"))
+        {
+            rewriteHandler.addRule(newRule(rule));
+        }
+        return rewriteHandler;
+    }
+
+    private static Rule newRule(PipelineNode node)
+    {
+        String type = node.getString("type");
+        Rule rule;
+        switch (type)
+        {
+            case "rewrite":
+                node.checkMembers(Set.of("type", "terminating", "pattern", "replacement"));
+                rule = new RewritePatternRule(node.getString("pattern"), node.getString("replacement"));
+                break;
+            case "rewriteRegex":
+                node.checkMembers(Set.of("type", "terminating", "regex", "replacement"));
+                rule = new RewriteRegexRule(regex(node), node.getString("replacement"));
+                break;
+            case "redirect":
+            {
+                node.checkMembers(Set.of("type", "terminating", "pattern", "location", "statusCode"));
+                RedirectPatternRule redirect = new RedirectPatternRule(node.getString("pattern"), node.getString("location"));
+                redirect.setStatusCode(redirectStatus(node));
+                rule = redirect;
+                break;
+            }
+            case "redirectRegex":
+            {
+                node.checkMembers(Set.of("type", "terminating", "regex", "location", "statusCode"));
+                RedirectRegexRule redirect = new RedirectRegexRule(regex(node), node.getString("location"));
+                redirect.setStatusCode(redirectStatus(node));
+                rule = redirect;
+                break;
+            }
+            case "header":
+            {
+                node.checkMembers(Set.of("type", "terminating", "pattern", "name", "value", "add"));
+                HeaderPatternRule header = new HeaderPatternRule(node.getString("pattern"), node.getString("name"), node.getString("value"));
+                header.setAdd(node.getBoolean("add", false));
+                rule = header;
+                break;
+            }
+            case "headerRegex":
+            {
+                node.checkMembers(Set.of("type", "terminating", "regex", "name", "value", "add"));
+                HeaderRegexRule header = new HeaderRegexRule(regex(node), node.getString("name"), node.getString("value"));
+                header.setAdd(node.getBoolean("add", false));
+                rule = header;
+                break;
+            }
+            case "response":
+            {
+                node.checkMembers(Set.of("type", "terminating", "pattern", "code", "message"));
+                int code = node.getInt("code", 0);
+                if (code < 100 || code > 599)
+                    throw node.invalid("code", "a status code", node.has("code") ? code : null);
+                rule = new ResponsePatternRule(node.getString("pattern"), String.valueOf(code), node.getString("message", null));
+                break;
+            }
+            default:
+                throw node.invalid("type", "one of [header, headerRegex, redirect, redirectRegex, response, rewrite, rewriteRegex]", type);
+        }
+        if (node.has("terminating"))
+            rule.setTerminating(node.getBoolean("terminating", false));
+        return rule;
+    }
+
+    private static String regex(PipelineNode node)
+    {
+        String regex = node.getString("regex");
+        try
+        {
+            Pattern.compile(regex);
+            return regex;
+        }
+        catch (PatternSyntaxException x)
+        {
+            throw node.invalid("regex", "a regular expression", regex);
+        }
+    }
+
+    private static int redirectStatus(PipelineNode node)
+    {
+        int status = node.getInt("statusCode", HttpStatus.FOUND_302);
+        if (!HttpStatus.isRedirection(status))
+            throw node.invalid("statusCode", "a redirect status code", status);
+        return status;
+    }
+}
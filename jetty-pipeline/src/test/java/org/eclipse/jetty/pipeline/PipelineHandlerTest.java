@@ -0,0 +1,300 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.pipeline;
+
+import java.io.IOException;
+import java.io.InterruptedIOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class PipelineHandlerTest
+{
+    private static final String CONTENT = "x".repeat(1024);
+
+    public WorkDir workDir;
+    private Path configFile;
+    private Server server;
+    private LocalConnector connector;
+    private PipelineHandler pipelineHandler;
+    private CountDownLatch slowHandling;
+    private CountDownLatch slowRelease;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        Path dir = workDir.getEmptyPathDir();
+        configFile = dir.resolve("pipeline.yaml");
+        Path staticDir = Files.createDirectories(dir.resolve("static"));
+        Files.writeString(staticDir.resolve("hello.txt"), "hello", StandardCharsets.UTF_8);
+
+        slowHandling = new CountDownLatch(1);
+        slowRelease = new CountDownLatch(1);
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        pipelineHandler = new PipelineHandler();
+        pipelineHandler.setConfigPath(configFile);
+        pipelineHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if (target.equals("/slow"))
+                {
+                    slowHandling.countDown();
+                    try
+                    {
+                        slowRelease.await(10, TimeUnit.SECONDS);
+                    }
+                    catch (InterruptedException x)
+                    {
+                        throw new InterruptedIOException();
+                    }
+                }
+                response.setContentType("text/plain");
+                response.getWriter().print(CONTENT);
+            }
+        });
+        server.setHandler(pipelineHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        slowRelease.countDown();
+        server.stop();
+    }
+
+    private HttpTester.Response request(String method, String path, String... headers) throws Exception
+    {
+        return HttpTester.parseResponse(connector.getResponse(rawRequest(method, path, headers)));
+    }
+
+    private static String rawRequest(String method, String path, String... headers)
+    {
+        StringBuilder request = new StringBuilder(method).append(" ").append(path).append(" HTTP/1.1\r\n");
+        request.append("Host: localhost\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        return request.append("Connection: close\r\n\r\n").toString();
+    }
+
+    private void writeConfig(String yaml) throws Exception
+    {
+        Files.writeString(configFile, yaml, StandardCharsets.UTF_8);
+    }
+
+    private static String versionConfig(int version)
+    {
+        return "pipeline:\n" +
+            "  - type: rewrite\n" +
+            "    rules:\n" +
+            "      - type: header\n" +
+            "        pattern: /*\n" +
+            "        name: X-Pipeline\n" +
+            "        value: " + version + "\n";
+    }
+
+    @Test
+    public void testStages() throws Exception
+    {
+        writeConfig("# Test pipeline.\n" +
+            "pipeline:\n" +
+            "  - type: gzip\n" +
+            "    minGzipSize: 512\n" +
+            "  - type: cors\n" +
+            "    allowedOrigins: [\"http://*.example.com\"]\n" +
+            "    allowedMethods: [GET, PUT]\n" +
+            "    chainPreflight: false\n" +
+            "  - type: rewrite\n" +
+            "    rules:\n" +
+            "      - type: redirect\n" +
+            "        pattern: /old/*\n" +
+            "        location: /new\n" +
+            "        statusCode: 301\n" +
+            "  - type: contexts\n" +
+            "    contexts:\n" +
+            "      - contextPath: /static\n" +
+            "        resourceBase: \"" + configFile.getParent().resolve("static").toUri() + "\"\n");
+        server.start();
+        assertThat(pipelineHandler.getGeneration(), is(1L));
+
+        HttpTester.Response response = request("GET", "/static/hello.txt");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello"));
+
+        response = request("GET", "/old/page");
+        assertThat(response.getStatus(), is(HttpStatus.MOVED_PERMANENTLY_301));
+        assertThat(response.get(HttpHeader.LOCATION), endsWith("/new"));
+
+        // Requests that match no context are forwarded to the wrapped handler.
+        response = request("GET", "/other", "Origin: http://www.example.com");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+        assertThat(response.get("Access-Control-Allow-Origin"), is("http://www.example.com"));
+
+        response = request("GET", "/other", "Origin: http://www.other.com");
+        assertThat(response.get("Access-Control-Allow-Origin"), nullValue());
+
+        // The preflight request is not forwarded.
+        response = request("OPTIONS", "/other", "Origin: http://www.example.com", "Access-Control-Request-Method: PUT");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("Access-Control-Allow-Methods"), containsString("PUT"));
+        assertThat(response.getContent(), not(is(CONTENT)));
+
+        response = request("GET", "/other", "Accept-Encoding: gzip");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+    }
+
+    @Test
+    public void testJSONConfiguration() throws Exception
+    {
+        configFile = configFile.resolveSibling("pipeline.json");
+        Files.writeString(configFile, "{\"pipeline\": [{\"type\": \"rewrite\", \"rules\": [{\"type\": \"response\", \"pattern\": \"/gone/*\", \"code\": 410}]}]}", StandardCharsets.UTF_8);
+        pipelineHandler.setConfigPath(configFile);
+        server.start();
+
+        assertThat(request("GET", "/gone/page").getStatus(), is(HttpStatus.GONE_410));
+        assertThat(request("GET", "/other").getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testInvalidConfiguration()
+    {
+        PipelineBuilder builder = new PipelineBuilder();
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"gzip\", \"minGzipSize\": \"big\"}]}", "pipeline[0].minGzipSize");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"unknown\"}]}", "pipeline[0].type");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"gzip\", \"minGzipSiz\": 1}]}", "Unknown member pipeline[0].minGzipSiz");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"contexts\", \"contexts\": [{\"contextPath\": \"/api\", \"routes\": [{\"proxyTo\": \"http://localhost:8080\"}]}]}, {\"type\": \"gzip\"}]}", "must be the last stage");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"contexts\", \"contexts\": [{\"contextPath\": \"/api\", \"routes\": [{\"proxyTo\": \"localhost\"}]}]}]}", "pipeline[0].contexts[0].routes[0].proxyTo");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"contexts\", \"contexts\": [{\"contextPath\": \"/api\", \"pipeline\": [{\"type\": \"contexts\"}], \"routes\": [{\"proxyTo\": \"http://localhost\"}]}]}]}", "pipeline[0].contexts[0].pipeline[0].contexts");
+        assertInvalid(builder, "{\"pipeline\": [{\"type\": \"rateLimit\", \"key\": \"cookie\"}]}", "pipeline[0].key");
+        assertInvalid(builder, "{\"stages\": []}", "Unknown member stages");
+    }
+
+    private static void assertInvalid(PipelineBuilder builder, String json, String expected)
+    {
+        IllegalArgumentException x = assertThrows(IllegalArgumentException.class, () -> builder.build(PipelineBuilder.parse(json, false), null));
+        assertThat(x.getMessage(), containsString(expected));
+    }
+
+    @Test
+    public void testInvalidConfigurationFailsStart() throws Exception
+    {
+        writeConfig("pipeline:\n  - type: qos\n");
+        IllegalArgumentException x = assertThrows(IllegalArgumentException.class, server::start);
+        assertThat(x.getMessage(), containsString("pipeline[0].maxConcurrency"));
+    }
+
+    @Test
+    public void testReloadDrainsRequests() throws Exception
+    {
+        writeConfig(versionConfig(1));
+        server.start();
+        Handler first = pipelineHandler.getPipeline();
+
+        LocalConnector.LocalEndPoint slowEndPoint = connector.executeRequest(rawRequest("GET", "/slow"));
+        assertTrue(slowHandling.await(5, TimeUnit.SECONDS));
+
+        writeConfig(versionConfig(2));
+        pipelineHandler.reload();
+        assertThat(pipelineHandler.getGeneration(), is(2L));
+        assertThat(pipelineHandler.getDrainingPipelines(), is(1));
+
+        // New requests are handled by the new pipeline,
+        // while the previous pipeline is still started.
+        assertThat(request("GET", "/fast").get("X-Pipeline"), is("2"));
+        assertThat(first.isStarted(), is(true));
+
+        slowRelease.countDown();
+        HttpTester.Response response = HttpTester.parseResponse(slowEndPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Pipeline"), is("1"));
+        await().atMost(5, TimeUnit.SECONDS).until(() -> pipelineHandler.getDrainingPipelines(), is(0));
+        assertThat(first.isStopped(), is(true));
+    }
+
+    @Test
+    public void testDrainTimeout() throws Exception
+    {
+        writeConfig(versionConfig(1));
+        pipelineHandler.setDrainTimeout(500);
+        server.start();
+        Handler first = pipelineHandler.getPipeline();
+
+        connector.executeRequest(rawRequest("GET", "/slow"));
+        assertTrue(slowHandling.await(5, TimeUnit.SECONDS));
+
+        writeConfig(versionConfig(2));
+        pipelineHandler.reload();
+
+        await().atMost(5, TimeUnit.SECONDS).until(() -> pipelineHandler.getDrainingPipelines(), is(0));
+        assertThat(first.isStopped(), is(true));
+    }
+
+    @Test
+    public void testHotReload() throws Exception
+    {
+        writeConfig(versionConfig(1));
+        pipelineHandler.setHotReload(true);
+        server.start();
+        assertThat(request("GET", "/").get("X-Pipeline"), is("1"));
+
+        // An invalid configuration retains the previous pipeline.
+        Thread.sleep(1001);
+        writeConfig("pipeline:\n  - type: gzip\n    minGzipSize: big\n");
+        Thread.sleep(2500);
+        assertThat(pipelineHandler.getGeneration(), is(1L));
+        assertThat(request("GET", "/").get("X-Pipeline"), is("1"));
+
+        writeConfig(versionConfig(2));
+        await().atMost(10, TimeUnit.SECONDS).until(() -> pipelineHandler.getGeneration(), is(2L));
+        assertThat(request("GET", "/").get("X-Pipeline"), is("2"));
+    }
+}
@@ -17,11 +17,14 @@ import java.net.InetAddress;
 import java.net.URI;
 import java.net.UnknownHostException;
 import java.util.Enumeration;
+import java.util.HashMap;
 import java.util.HashSet;
 import java.util.Iterator;
 import java.util.Locale;
+import java.util.Map;
 import java.util.Set;
 import java.util.concurrent.Executor;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.TimeoutException;
 import java.util.stream.Collectors;
 import java.util.stream.Stream;
@@ -40,12 +43,15 @@ import org.eclipse.jetty.client.ProtocolHandlers;
 import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.client.api.Response;
 import org.eclipse.jetty.client.dynamic.HttpClientTransportDynamic;
+import org.eclipse.jetty.http.BadMessageException;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpHeaderValue;
 import org.eclipse.jetty.http.HttpScheme;
 import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.UriCanonicalizer;
 import org.eclipse.jetty.io.ClientConnector;
 import org.eclipse.jetty.util.HttpCookieStore;
 import org.eclipse.jetty.util.StringUtil;
@@ -80,6 +86,10 @@ import org.slf4j.LoggerFactory;
  * <p>The default behavior of not preserving the Host header mimics
  * the default behavior of Apache httpd and Nginx, which both have
  * a way to be configured to preserve the Host header.</p>
+ * <p>Clients that send {@code TE: trailers}, such as gRPC clients, have
+ * request and response trailers proxied; gRPC requests additionally
+ * have their {@code grpc-timeout} mapped to the proxy request timeout,
+ * and proxy failures reported as gRPC trailers-only responses.</p>
  */
 public abstract class AbstractProxyServlet extends HttpServlet
 {
@@ -95,6 +105,12 @@ public abstract class AbstractProxyServlet extends HttpServlet
         "trailer",
         "upgrade"
     );
+    private static final String GRPC_CONTENT_TYPE = "application/grpc";
+    private static final String GRPC_TIMEOUT_HEADER = "grpc-timeout";
+    private static final String GRPC_STATUS_HEADER = "grpc-status";
+    private static final String GRPC_MESSAGE_HEADER = "grpc-message";
+    private static final int GRPC_DEADLINE_EXCEEDED = 4;
+    private static final int GRPC_UNAVAILABLE = 14;
 
     private final Set<String> _whiteList = new HashSet<>();
     private final Set<String> _blackList = new HashSet<>();
@@ -189,6 +205,69 @@ public abstract class AbstractProxyServlet extends HttpServlet
         this._timeout = timeout;
     }
 
+    /**
+     * <p>Returns the timeout of the proxy request for the given client request.</p>
+     * <p>A {@code grpc-timeout} header sent by the client is honored when its
+     * deadline is shorter than {@link #getTimeout()}.</p>
+     *
+     * @param clientRequest the client request
+     * @return the timeout of the proxy request, in milliseconds
+     */
+    protected long getTimeout(HttpServletRequest clientRequest)
+    {
+        long timeout = getTimeout();
+        long grpcTimeout = parseGrpcTimeout(clientRequest.getHeader(GRPC_TIMEOUT_HEADER));
+        if (grpcTimeout > 0 && (timeout <= 0 || grpcTimeout < timeout))
+            return grpcTimeout;
+        return timeout;
+    }
+
+    private static long parseGrpcTimeout(String value)
+    {
+        // Format is 1 to 8 ASCII digits followed by a unit.
+        if (value == null)
+            return -1;
+        int length = value.length();
+        if (length < 2 || length > 9)
+            return -1;
+        for (int i = 0; i < length - 1; ++i)
+        {
+            char c = value.charAt(i);
+            if (c < '0' || c > '9')
+                return -1;
+        }
+        TimeUnit unit;
+        switch (value.charAt(length - 1))
+        {
+            case 'H':
+                unit = TimeUnit.HOURS;
+                break;
+            case 'M':
+                unit = TimeUnit.MINUTES;
+                break;
+            case 'S':
+                unit = TimeUnit.SECONDS;
+                break;
+            case 'm':
+                unit = TimeUnit.MILLISECONDS;
+                break;
+            case 'u':
+                unit = TimeUnit.MICROSECONDS;
+                break;
+            case 'n':
+                unit = TimeUnit.NANOSECONDS;
+                break;
+            default:
+                return -1;
+        }
+        long amount = Long.parseLong(value.substring(0, length - 1));
+        long millis = unit.toMillis(amount);
+        // Round up, as a zero timeout would mean no timeout at all.
+        if (millis == 0 || unit.toNanos(amount) > TimeUnit.MILLISECONDS.toNanos(millis))
+            ++millis;
+        return millis;
+    }
+
     public Set<String> getWhiteListHosts()
     {
         return _whiteList;
@@ -511,6 +590,10 @@ public abstract class AbstractProxyServlet extends HttpServlet
         if (_hostHeader != null)
             newHeaders.add(HttpHeader.HOST, _hostHeader);
 
+        // TE is hop-by-hop, but "trailers" must be forwarded for the server to send trailers.
+        if (acceptsTrailers(clientRequest))
+            newHeaders.add(HttpHeader.TE, "trailers");
+
         proxyRequest.headers(headers -> headers.clear().add(newHeaders));
     }
 
@@ -535,6 +618,58 @@ public abstract class AbstractProxyServlet extends HttpServlet
         return hopHeaders;
     }
 
+    /**
+     * <p>Copies the client request trailers, if any, to the proxy request.</p>
+     * <p>Trailers are only proxied for client requests with {@code TE: trailers}
+     * and content of unknown length, so that other requests are not forced to
+     * be sent with the chunked transfer coding.</p>
+     *
+     * @param clientRequest the client request
+     * @param proxyRequest the request being proxied
+     */
+    protected void copyRequestTrailers(HttpServletRequest clientRequest, Request proxyRequest)
+    {
+        if (!hasContent(clientRequest) || clientRequest.getContentLengthLong() >= 0 || !acceptsTrailers(clientRequest))
+            return;
+        proxyRequest.trailers(() ->
+        {
+            // Called after all the client request content has been read.
+            Map<String, String> trailerFields = clientRequest.getTrailerFields();
+            if (trailerFields.isEmpty())
+                return null;
+            HttpFields.Mutable trailers = HttpFields.build();
+            trailerFields.forEach(trailers::add);
+            return trailers.asImmutable();
+        });
+    }
+
+    /**
+     * @param clientRequest the client request
+     * @return whether the client has declared, via {@code TE: trailers}, that it accepts response trailers
+     */
+    protected boolean acceptsTrailers(HttpServletRequest clientRequest)
+    {
+        for (Enumeration<String> values = clientRequest.getHeaders(HttpHeader.TE.asString()); values.hasMoreElements(); )
+        {
+            for (String value : values.nextElement().split(","))
+            {
+                if ("trailers".equalsIgnoreCase(value.trim()))
+                    return true;
+            }
+        }
+        return false;
+    }
+
+    /**
+     * @param clientRequest the client request
+     * @return whether the client request is a gRPC request, as per its {@code Content-Type}
+     */
+    protected boolean isGrpcRequest(HttpServletRequest clientRequest)
+    {
+        String contentType = clientRequest.getContentType();
+        return contentType != null && contentType.regionMatches(true, 0, GRPC_CONTENT_TYPE, 0, GRPC_CONTENT_TYPE.length());
+    }
+
     protected void addProxyHeaders(HttpServletRequest clientRequest, Request proxyRequest)
     {
         addViaHeader(proxyRequest);
@@ -672,6 +807,11 @@ public abstract class AbstractProxyServlet extends HttpServlet
             proxyResponse.addHeader(headerName, newHeaderValue);
         }
 
+        // The trailers are retrieved when the proxy response is completed,
+        // after the whole server response has been received.
+        if (acceptsTrailers(clientRequest) && !HttpVersion.HTTP_1_0.is(clientRequest.getProtocol()))
+            proxyResponse.setTrailerFields(() -> serverResponseTrailers(clientRequest, serverResponse));
+
         if (_log.isDebugEnabled())
         {
             StringBuilder builder = new StringBuilder(System.lineSeparator());
@@ -702,6 +842,24 @@ public abstract class AbstractProxyServlet extends HttpServlet
         return headerValue;
     }
 
+    /**
+     * @param clientRequest the client request
+     * @param serverResponse the server response
+     * @return the trailers to send to the client, or {@code null} if the server response has no trailers
+     */
+    protected Map<String, String> serverResponseTrailers(HttpServletRequest clientRequest, Response serverResponse)
+    {
+        HttpFields trailers = serverResponse.getTrailers();
+        if (trailers == null || trailers.size() == 0)
+            return null;
+        Map<String, String> result = new HashMap<>();
+        for (HttpField field : trailers)
+        {
+            result.merge(field.getLowerCaseName(), field.getValue(), (v1, v2) -> v1 + "," + v2);
+        }
+        return result;
+    }
+
     protected void onProxyResponseSuccess(HttpServletRequest clientRequest, HttpServletResponse proxyResponse, Response serverResponse)
     {
         if (_log.isDebugEnabled())
@@ -716,6 +874,12 @@ public abstract class AbstractProxyServlet extends HttpServlet
         if (_log.isDebugEnabled())
             _log.debug(getRequestId(clientRequest) + " proxying failed", failure);
 
+        if (isGrpcRequest(clientRequest) && !proxyResponse.isCommitted())
+        {
+            sendGrpcProxyResponseError(clientRequest, proxyResponse, failure);
+            return;
+        }
+
         int status = proxyResponseStatus(failure);
         int serverStatus = serverResponse == null ? status : serverResponse.getStatus();
         if (expects100Continue(clientRequest) && serverStatus >= HttpStatus.OK_200)
@@ -765,6 +929,37 @@ public abstract class AbstractProxyServlet extends HttpServlet
         }
     }
 
+    /**
+     * <p>Sends a gRPC trailers-only response for a proxy failure.</p>
+     * <p>gRPC clients expect the failure to be reported by the {@code grpc-status}
+     * header of a {@code 200} response, rather than by the HTTP status code.</p>
+     *
+     * @param clientRequest the client request
+     * @param proxyResponse the proxy response
+     * @param failure the proxy failure
+     */
+    protected void sendGrpcProxyResponseError(HttpServletRequest clientRequest, HttpServletResponse proxyResponse, Throwable failure)
+    {
+        try
+        {
+            boolean timeout = failure instanceof TimeoutException;
+            proxyResponse.reset();
+            proxyResponse.setStatus(HttpStatus.OK_200);
+            proxyResponse.setContentType(GRPC_CONTENT_TYPE);
+            proxyResponse.setHeader(GRPC_STATUS_HEADER, String.valueOf(timeout ? GRPC_DEADLINE_EXCEEDED : GRPC_UNAVAILABLE));
+            proxyResponse.setHeader(GRPC_MESSAGE_HEADER, timeout ? "Proxy deadline exceeded" : "Proxy upstream unavailable");
+        }
+        catch (Exception e)
+        {
+            _log.trace("IGNORED", e);
+        }
+        finally
+        {
+            if (clientRequest.isAsyncStarted())
+                clientRequest.getAsyncContext().complete();
+        }
+    }
+
     protected void onContinue(HttpServletRequest clientRequest, Request proxyRequest)
     {
         if (_log.isDebugEnabled())
@@ -828,7 +1023,25 @@ public abstract class AbstractProxyServlet extends HttpServlet
             if (!rest.isEmpty())
             {
                 if (!rest.startsWith("/"))
-                    uri.append("/");
+                    rest = "/" + rest;
+                // Proxy the canonical path, so that the server does not
+                // interpret the path differently from this proxy.
+                org.eclipse.jetty.server.Request baseRequest = org.eclipse.jetty.server.Request.getBaseRequest(request);
+                if (baseRequest != null)
+                {
+                    try
+                    {
+                        UriCanonicalizer.CanonicalPath canonical = baseRequest.canonicalizePath(rest);
+                        if (canonical != null)
+                            rest = canonical.getPath();
+                    }
+                    catch (BadMessageException x)
+                    {
+                        if (proxyServlet._log.isDebugEnabled())
+                            proxyServlet._log.debug("Rejected non canonical path {}", path, x);
+                        return null;
+                    }
+                }
                 uri.append(rest);
             }
 
@@ -91,12 +91,14 @@ public class AsyncMiddleManServlet extends AbstractProxyServlet
 
         copyRequestHeaders(clientRequest, proxyRequest);
 
+        copyRequestTrailers(clientRequest, proxyRequest);
+
         addProxyHeaders(clientRequest, proxyRequest);
 
         final AsyncContext asyncContext = clientRequest.startAsync();
         // We do not timeout the continuation, but the proxy request.
         asyncContext.setTimeout(0);
-        proxyRequest.timeout(getTimeout(), TimeUnit.MILLISECONDS);
+        proxyRequest.timeout(getTimeout(clientRequest), TimeUnit.MILLISECONDS);
 
         // If there is content, the send of the proxy request
         // is delayed and performed when the content arrives,
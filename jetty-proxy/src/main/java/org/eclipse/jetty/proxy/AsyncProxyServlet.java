@@ -238,6 +238,9 @@ public class AsyncProxyServlet extends ProxyServlet
                     _log.debug("{} asynchronous write start of {} bytes on {}", requestId, length, output);
                 output.write(buffer, offset, length);
                 state = WriteState.PENDING;
+                // gRPC messages must not be held in the aggregation buffer.
+                if (isGrpcRequest(request) && output.isReady())
+                    output.flush();
                 if (output.isReady())
                 {
                     if (_log.isDebugEnabled())
@@ -0,0 +1,228 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.net.URI;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>An upstream server that {@link BalancerServlet} proxies requests to.</p>
+ * <p>A backend tracks the statistics used by {@link LoadBalancingPolicy}
+ * implementations, such as the number of active requests and the latency,
+ * and its availability, that depends on the result of the active health
+ * checks and on the passive outlier detection.</p>
+ */
+public class Backend
+{
+    private static final double EWMA_WEIGHT = 0.3;
+
+    private final AutoLock _lock = new AutoLock();
+    private final AtomicInteger _activeRequests = new AtomicInteger();
+    private final LongAdder _requests = new LongAdder();
+    private final LongAdder _failures = new LongAdder();
+    private final String _name;
+    private final String _proxyTo;
+    private final URI _backendURI;
+    private volatile boolean _healthy = true;
+    private volatile long _ejectedUntil;
+    private volatile boolean _ejected;
+    private volatile double _latency;
+    private int _consecutiveFailures;
+    private int _ejections;
+    private int _healthChecks;
+
+    public Backend(String name, String proxyTo)
+    {
+        _name = name;
+        _proxyTo = proxyTo;
+        _backendURI = URI.create(_proxyTo).normalize();
+    }
+
+    public String getName()
+    {
+        return _name;
+    }
+
+    public String getProxyTo()
+    {
+        return _proxyTo;
+    }
+
+    public URI getBackendURI()
+    {
+        return _backendURI;
+    }
+
+    /**
+     * @return the number of requests currently proxied to this backend
+     */
+    public int getActiveRequests()
+    {
+        return _activeRequests.get();
+    }
+
+    /**
+     * @return the total number of requests proxied to this backend
+     */
+    public long getRequests()
+    {
+        return _requests.sum();
+    }
+
+    /**
+     * @return the total number of requests that failed because of this backend
+     */
+    public long getFailures()
+    {
+        return _failures.sum();
+    }
+
+    /**
+     * @return the exponentially weighted moving average of the latency
+     * of the successful requests, in nanoseconds, or 0 if no request succeeded yet
+     */
+    public double getLatency()
+    {
+        return _latency;
+    }
+
+    /**
+     * @return whether this backend passes the active health checks
+     */
+    public boolean isHealthy()
+    {
+        return _healthy;
+    }
+
+    /**
+     * @return whether this backend is temporarily ejected because of consecutive failures
+     */
+    public boolean isEjected()
+    {
+        if (!_ejected)
+            return false;
+        if (NanoTime.isBefore(NanoTime.now(), _ejectedUntil))
+            return true;
+        _ejected = false;
+        return false;
+    }
+
+    /**
+     * @return whether requests can be proxied to this backend
+     */
+    public boolean isAvailable()
+    {
+        return isHealthy() && !isEjected();
+    }
+
+    void onRequestBegin()
+    {
+        _activeRequests.incrementAndGet();
+        _requests.increment();
+    }
+
+    /**
+     * @param latency the latency of the request in nanoseconds
+     * @param failed whether the request failed because of this backend
+     * @return the number of consecutive failed requests
+     */
+    int onRequestComplete(long latency, boolean failed)
+    {
+        _activeRequests.decrementAndGet();
+        if (failed)
+            _failures.increment();
+        try (AutoLock l = _lock.lock())
+        {
+            // Only successful requests update the latency,
+            // so that fast failures do not attract more requests.
+            if (failed)
+                return ++_consecutiveFailures;
+            double current = _latency;
+            _latency = current == 0 ? latency : current + EWMA_WEIGHT * (latency - current);
+            _consecutiveFailures = 0;
+            _ejections = 0;
+            return 0;
+        }
+    }
+
+    /**
+     * <p>Ejects this backend for the given base time, multiplied
+     * by the number of ejections since the last successful request.</p>
+     *
+     * @param baseEjectionTime the base ejection time in milliseconds
+     */
+    void eject(long baseEjectionTime)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            ++_ejections;
+            _consecutiveFailures = 0;
+            _ejectedUntil = NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(baseEjectionTime * _ejections);
+            _ejected = true;
+        }
+    }
+
+    /**
+     * @param success whether the health check succeeded
+     * @param healthyThreshold the consecutive successful checks that make an unhealthy backend healthy
+     * @param unhealthyThreshold the consecutive failed checks that make a healthy backend unhealthy
+     * @return whether the health of this backend changed
+     */
+    boolean onHealthCheck(boolean success, int healthyThreshold, int unhealthyThreshold)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (success == _healthy)
+            {
+                _healthChecks = 0;
+                return false;
+            }
+            if (++_healthChecks < (success ? healthyThreshold : unhealthyThreshold))
+                return false;
+            _healthChecks = 0;
+            _healthy = success;
+            return true;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s[name=%s,proxyTo=%s,healthy=%b,ejected=%b,active=%d]", getClass().getSimpleName(), _name, _proxyTo, isHealthy(), isEjected(), getActiveRequests());
+    }
+
+    @Override
+    public int hashCode()
+    {
+        return _name.hashCode();
+    }
+
+    @Override
+    public boolean equals(Object obj)
+    {
+        if (this == obj)
+            return true;
+        if (obj == null)
+            return false;
+        if (getClass() != obj.getClass())
+            return false;
+        Backend that = (Backend)obj;
+        return _name.equals(that._name);
+    }
+}
@@ -13,6 +13,8 @@
 
 package org.eclipse.jetty.proxy;
 
+import java.net.ConnectException;
+import java.net.SocketTimeoutException;
 import java.net.URI;
 import java.util.ArrayList;
 import java.util.Collections;
@@ -20,17 +22,54 @@ import java.util.HashSet;
 import java.util.LinkedList;
 import java.util.List;
 import java.util.Set;
-import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.TimeUnit;
+import java.util.stream.Collectors;
+import javax.servlet.ServletConfig;
 import javax.servlet.ServletException;
 import javax.servlet.UnavailableException;
 import javax.servlet.http.Cookie;
 import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.client.api.Request;
 import org.eclipse.jetty.client.api.Response;
+import org.eclipse.jetty.client.api.Result;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.URIUtil;
-
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A {@link ProxyServlet} that balances the requests among multiple {@link Backend}s,
+ * each configured with a {@code balancerMember.<name>.proxyTo} init parameter.</p>
+ * <p>The backend of a request is selected among the available backends:</p>
+ * <ul>
+ * <li>if {@code stickySessions} is true, by the worker name suffix of the session ID;</li>
+ * <li>otherwise, if {@code stickyCookie} is set, by the value of the cookie with that name,
+ * that this servlet sets to the name of the backend that served the request;</li>
+ * <li>otherwise, if {@code stickyHash} is set, by the rendezvous hash of the request header
+ * with that name, or of the client address if the value is {@code remoteAddr};</li>
+ * <li>otherwise by the {@link LoadBalancingPolicy} configured with the
+ * {@code loadBalancingPolicy} init parameter, by default {@code roundRobin}.</li>
+ * </ul>
+ * <p>A backend is available when it is healthy and not ejected:</p>
+ * <ul>
+ * <li>if {@code healthCheckPath} is set, each backend is periodically sent a {@code GET}
+ * request for that path, and becomes unhealthy after {@code unhealthyThreshold} consecutive
+ * failed checks, and healthy again after {@code healthyThreshold} consecutive successful
+ * checks;</li>
+ * <li>a backend that fails {@code outlierConsecutiveFailures} consecutive requests, with
+ * a 5xx status code or a connect failure, is ejected for {@code outlierEjectionTime}
+ * milliseconds, multiplied by the number of ejections since its last successful request,
+ * unless more than {@code outlierMaxEjectionPercent} percent of the backends would
+ * be ejected.</li>
+ * </ul>
+ * <p>Requests are rejected with status {@code 503} when no backend is available.</p>
+ */
 public class BalancerServlet extends ProxyServlet
 {
+    private static final String BACKEND_ATTRIBUTE = BalancerServlet.class.getName() + ".backend";
+    private static final String REMOTE_ADDR = "remoteAddr";
     private static final String BALANCER_MEMBER_PREFIX = "balancerMember.";
     private static final List<String> FORBIDDEN_CONFIG_PARAMETERS;
 
@@ -57,10 +96,22 @@ public class BalancerServlet extends ProxyServlet
     private static final String JSESSIONID = "jsessionid";
     private static final String JSESSIONID_URL_PREFIX = JSESSIONID + "=";
 
-    private final List<BalancerMember> _balancerMembers = new ArrayList<>();
-    private final AtomicLong counter = new AtomicLong();
+    private final AutoLock _lock = new AutoLock();
+    private final List<Backend> _backends = new ArrayList<>();
+    private LoadBalancingPolicy _policy;
     private boolean _stickySessions;
+    private String _stickyCookie;
+    private String _stickyHash;
     private boolean _proxyPassReverse;
+    private String _healthCheckPath;
+    private long _healthCheckInterval;
+    private long _healthCheckTimeout;
+    private int _healthyThreshold;
+    private int _unhealthyThreshold;
+    private int _outlierConsecutiveFailures;
+    private long _outlierEjectionTime;
+    private int _outlierMaxEjectionPercent;
+    private volatile boolean _destroyed;
 
     @Override
     public void init() throws ServletException
@@ -70,6 +121,23 @@ public class BalancerServlet extends ProxyServlet
         initStickySessions();
         initBalancers();
         initProxyPassReverse();
+        initLoadBalancing();
+        initHealthChecks();
+    }
+
+    @Override
+    public void destroy()
+    {
+        _destroyed = true;
+        super.destroy();
+    }
+
+    /**
+     * @return the backends of this balancer
+     */
+    public List<Backend> getBackends()
+    {
+        return Collections.unmodifiableList(_backends);
     }
 
     private void validateConfig() throws ServletException
@@ -85,21 +153,24 @@ public class BalancerServlet extends ProxyServlet
 
     private void initStickySessions()
     {
-        _stickySessions = Boolean.parseBoolean(getServletConfig().getInitParameter("stickySessions"));
+        ServletConfig config = getServletConfig();
+        _stickySessions = Boolean.parseBoolean(config.getInitParameter("stickySessions"));
+        _stickyCookie = config.getInitParameter("stickyCookie");
+        _stickyHash = config.getInitParameter("stickyHash");
     }
 
     private void initBalancers() throws ServletException
     {
-        Set<BalancerMember> members = new HashSet<>();
+        Set<Backend> members = new HashSet<>();
         for (String balancerName : getBalancerNames())
         {
             String memberProxyToParam = BALANCER_MEMBER_PREFIX + balancerName + ".proxyTo";
             String proxyTo = getServletConfig().getInitParameter(memberProxyToParam);
             if (proxyTo == null || proxyTo.trim().length() == 0)
                 throw new UnavailableException(memberProxyToParam + " parameter is empty.");
-            members.add(new BalancerMember(balancerName, proxyTo));
+            members.add(new Backend(balancerName, proxyTo));
         }
-        _balancerMembers.addAll(members);
+        _backends.addAll(members);
     }
 
     private void initProxyPassReverse()
@@ -107,6 +178,59 @@ public class BalancerServlet extends ProxyServlet
         _proxyPassReverse = Boolean.parseBoolean(getServletConfig().getInitParameter("proxyPassReverse"));
     }
 
+    private void initLoadBalancing() throws ServletException
+    {
+        ServletConfig config = getServletConfig();
+        try
+        {
+            _policy = newLoadBalancingPolicy(config.getInitParameter("loadBalancingPolicy"));
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new UnavailableException(x.getMessage());
+        }
+        _outlierConsecutiveFailures = getIntInitParameter("outlierConsecutiveFailures", 5);
+        _outlierEjectionTime = getLongInitParameter("outlierEjectionTime", 30000);
+        _outlierMaxEjectionPercent = getIntInitParameter("outlierMaxEjectionPercent", 50);
+    }
+
+    /**
+     * @param name the value of the {@code loadBalancingPolicy} init parameter, or null if it is not set
+     * @return the policy that selects the backend of requests
+     * @throws IllegalArgumentException if the policy name is unknown
+     */
+    protected LoadBalancingPolicy newLoadBalancingPolicy(String name)
+    {
+        return LoadBalancingPolicy.from(name == null ? "roundRobin" : name);
+    }
+
+    private void initHealthChecks()
+    {
+        _healthCheckPath = getServletConfig().getInitParameter("healthCheckPath");
+        _healthCheckInterval = getLongInitParameter("healthCheckInterval", 10000);
+        _healthCheckTimeout = getLongInitParameter("healthCheckTimeout", 5000);
+        _healthyThreshold = getIntInitParameter("healthyThreshold", 2);
+        _unhealthyThreshold = getIntInitParameter("unhealthyThreshold", 2);
+        if (_healthCheckPath == null)
+            return;
+        for (Backend backend : _backends)
+        {
+            scheduleHealthCheck(backend, 0);
+        }
+    }
+
+    private int getIntInitParameter(String name, int defaultValue)
+    {
+        String value = getServletConfig().getInitParameter(name);
+        return value == null ? defaultValue : Integer.parseInt(value);
+    }
+
+    private long getLongInitParameter(String name, long defaultValue)
+    {
+        String value = getServletConfig().getInitParameter(name);
+        return value == null ? defaultValue : Long.parseLong(value);
+    }
+
     private Set<String> getBalancerNames() throws ServletException
     {
         Set<String> names = new HashSet<>();
@@ -127,38 +251,199 @@ public class BalancerServlet extends ProxyServlet
     @Override
     protected String rewriteTarget(HttpServletRequest request)
     {
-        BalancerMember balancerMember = selectBalancerMember(request);
+        Backend backend = selectBackend(request);
         if (_log.isDebugEnabled())
-            _log.debug("Selected {}", balancerMember);
+            _log.debug("Selected {}", backend);
+        if (backend == null)
+            return null;
+        request.setAttribute(BACKEND_ATTRIBUTE, backend);
         String path = request.getRequestURI();
         String query = request.getQueryString();
         if (query != null)
             path += "?" + query;
-        return URI.create(balancerMember.getProxyTo() + "/" + path).normalize().toString();
+        return URI.create(backend.getProxyTo() + "/" + path).normalize().toString();
     }
 
-    private BalancerMember selectBalancerMember(HttpServletRequest request)
+    @Override
+    protected void onProxyRewriteFailed(HttpServletRequest clientRequest, HttpServletResponse proxyResponse)
+    {
+        sendProxyResponseError(clientRequest, proxyResponse, HttpStatus.SERVICE_UNAVAILABLE_503);
+    }
+
+    private Backend selectBackend(HttpServletRequest request)
     {
+        List<Backend> available = _backends.stream()
+            .filter(Backend::isAvailable)
+            .collect(Collectors.toList());
+        if (available.isEmpty())
+            return null;
+
         if (_stickySessions)
         {
-            String name = getBalancerMemberNameFromSessionId(request);
-            if (name != null)
+            Backend backend = findBackendByName(available, getBalancerMemberNameFromSessionId(request));
+            if (backend != null)
+                return backend;
+        }
+        if (_stickyCookie != null)
+        {
+            Backend backend = findBackendByName(available, getCookieValue(request, _stickyCookie));
+            if (backend != null)
+                return backend;
+        }
+        if (_stickyHash != null)
+        {
+            String key = REMOTE_ADDR.equals(_stickyHash) ? request.getRemoteAddr() : request.getHeader(_stickyHash);
+            if (key != null)
+                return selectBackendByHash(available, key);
+        }
+        return _policy.select(available, request);
+    }
+
+    private Backend findBackendByName(List<Backend> backends, String name)
+    {
+        if (name == null)
+            return null;
+        for (Backend backend : backends)
+        {
+            if (backend.getName().equals(name))
+                return backend;
+        }
+        return null;
+    }
+
+    /**
+     * <p>Selects the backend with the highest hash of the key and of the backend name,
+     * so that only the keys of a backend move when it becomes unavailable.</p>
+     */
+    private Backend selectBackendByHash(List<Backend> backends, String key)
+    {
+        Backend result = null;
+        long highest = 0;
+        for (Backend backend : backends)
+        {
+            long hash = mix(key.hashCode() * 31L + backend.getName().hashCode());
+            if (result == null || hash > highest)
+            {
+                result = backend;
+                highest = hash;
+            }
+        }
+        return result;
+    }
+
+    private static long mix(long hash)
+    {
+        // The finalizer of the 64-bit MurmurHash3.
+        hash ^= hash >>> 33;
+        hash *= 0xFF51AFD7ED558CCDL;
+        hash ^= hash >>> 33;
+        hash *= 0xC4CEB9FE1A85EC53L;
+        hash ^= hash >>> 33;
+        return hash;
+    }
+
+    @Override
+    protected Request newProxyRequest(HttpServletRequest request, String rewrittenTarget)
+    {
+        Request proxyRequest = super.newProxyRequest(request, rewrittenTarget);
+        Backend backend = (Backend)request.getAttribute(BACKEND_ATTRIBUTE);
+        if (backend != null)
+        {
+            long begin = NanoTime.now();
+            backend.onRequestBegin();
+            proxyRequest.onComplete(result -> onBackendComplete(backend, result, NanoTime.since(begin)));
+        }
+        return proxyRequest;
+    }
+
+    private void onBackendComplete(Backend backend, Result result, long latency)
+    {
+        boolean failed = isBackendFailure(result);
+        int failures = backend.onRequestComplete(latency, failed);
+        if (_outlierConsecutiveFailures > 0 && failures >= _outlierConsecutiveFailures)
+            eject(backend);
+    }
+
+    /**
+     * @param result the result of the request proxied to a backend
+     * @return whether the request failed because of the backend, by default
+     * when the status code is 5xx or the connection to the backend failed
+     */
+    protected boolean isBackendFailure(Result result)
+    {
+        Response response = result.getResponse();
+        if (response != null && HttpStatus.isServerError(response.getStatus()))
+            return true;
+        Throwable failure = result.getFailure();
+        return failure instanceof ConnectException || failure instanceof SocketTimeoutException;
+    }
+
+    private void eject(Backend backend)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            long ejected = _backends.stream().filter(Backend::isEjected).count();
+            if (!backend.isEjected() && (ejected + 1) * 100 > (long)_outlierMaxEjectionPercent * _backends.size())
             {
-                BalancerMember balancerMember = findBalancerMemberByName(name);
-                if (balancerMember != null)
-                    return balancerMember;
+                if (_log.isDebugEnabled())
+                    _log.debug("Not ejecting {}, max ejection percent reached", backend);
+                return;
             }
+            backend.eject(_outlierEjectionTime);
         }
-        int index = (int)(counter.getAndIncrement() % _balancerMembers.size());
-        return _balancerMembers.get(index);
+        if (_log.isDebugEnabled())
+            _log.debug("Ejected {}", backend);
     }
 
-    private BalancerMember findBalancerMemberByName(String name)
+    private void scheduleHealthCheck(Backend backend, long delay)
     {
-        for (BalancerMember balancerMember : _balancerMembers)
+        if (!_destroyed)
+            getHttpClient().getScheduler().schedule(() -> checkHealth(backend), delay, TimeUnit.MILLISECONDS);
+    }
+
+    private void checkHealth(Backend backend)
+    {
+        if (_destroyed)
+            return;
+        getHttpClient().newRequest(URI.create(backend.getProxyTo() + "/" + _healthCheckPath).normalize())
+            .timeout(_healthCheckTimeout, TimeUnit.MILLISECONDS)
+            .send(result ->
+            {
+                int status = result.getResponse().getStatus();
+                boolean success = result.isSucceeded() && status >= HttpStatus.OK_200 && status < HttpStatus.BAD_REQUEST_400;
+                if (backend.onHealthCheck(success, _healthyThreshold, _unhealthyThreshold))
+                {
+                    if (_log.isDebugEnabled())
+                        _log.debug("Health changed {}", backend);
+                }
+                scheduleHealthCheck(backend, _healthCheckInterval);
+            });
+    }
+
+    @Override
+    protected void onServerResponseHeaders(HttpServletRequest clientRequest, HttpServletResponse proxyResponse, Response serverResponse)
+    {
+        super.onServerResponseHeaders(clientRequest, proxyResponse, serverResponse);
+        Backend backend = (Backend)clientRequest.getAttribute(BACKEND_ATTRIBUTE);
+        if (_stickyCookie != null && backend != null && !backend.getName().equals(getCookieValue(clientRequest, _stickyCookie)))
+        {
+            Cookie cookie = new Cookie(_stickyCookie, backend.getName());
+            cookie.setPath("/");
+            cookie.setHttpOnly(true);
+            proxyResponse.addCookie(cookie);
+        }
+    }
+
+    private String getCookieValue(HttpServletRequest request, String name)
+    {
+        Cookie[] cookies = request.getCookies();
+        if (cookies != null)
         {
-            if (balancerMember.getName().equals(name))
-                return balancerMember;
+            for (Cookie cookie : cookies)
+            {
+                if (name.equals(cookie.getName()))
+                    return cookie.getValue();
+            }
         }
         return null;
     }
@@ -235,9 +520,9 @@ public class BalancerServlet extends ProxyServlet
 
     private boolean isBackendLocation(URI locationURI)
     {
-        for (BalancerMember balancerMember : _balancerMembers)
+        for (Backend backend : _backends)
         {
-            URI backendURI = balancerMember.getBackendURI();
+            URI backendURI = backend.getBackendURI();
             if (backendURI.getHost().equals(locationURI.getHost()) &&
                 backendURI.getScheme().equals(locationURI.getScheme()) &&
                 backendURI.getPort() == locationURI.getPort())
@@ -253,58 +538,4 @@ public class BalancerServlet extends ProxyServlet
     {
         return true;
     }
-
-    private static class BalancerMember
-    {
-        private final String _name;
-        private final String _proxyTo;
-        private final URI _backendURI;
-
-        public BalancerMember(String name, String proxyTo)
-        {
-            _name = name;
-            _proxyTo = proxyTo;
-            _backendURI = URI.create(_proxyTo).normalize();
-        }
-
-        public String getName()
-        {
-            return _name;
-        }
-
-        public String getProxyTo()
-        {
-            return _proxyTo;
-        }
-
-        public URI getBackendURI()
-        {
-            return _backendURI;
-        }
-
-        @Override
-        public String toString()
-        {
-            return String.format("%s[name=%s,proxyTo=%s]", getClass().getSimpleName(), _name, _proxyTo);
-        }
-
-        @Override
-        public int hashCode()
-        {
-            return _name.hashCode();
-        }
-
-        @Override
-        public boolean equals(Object obj)
-        {
-            if (this == obj)
-                return true;
-            if (obj == null)
-                return false;
-            if (getClass() != obj.getClass())
-                return false;
-            BalancerMember that = (BalancerMember)obj;
-            return _name.equals(that._name);
-        }
-    }
 }
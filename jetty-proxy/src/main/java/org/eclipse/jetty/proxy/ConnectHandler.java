@@ -16,11 +16,16 @@ package org.eclipse.jetty.proxy;
 import java.io.Closeable;
 import java.io.IOException;
 import java.net.InetSocketAddress;
+import java.net.Socket;
+import java.net.SocketAddress;
 import java.nio.ByteBuffer;
+import java.nio.channels.DatagramChannel;
 import java.nio.channels.SelectableChannel;
 import java.nio.channels.SelectionKey;
 import java.nio.channels.SocketChannel;
+import java.time.Instant;
 import java.util.HashSet;
+import java.util.Objects;
 import java.util.Set;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
@@ -32,14 +37,18 @@ import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.http.CapsuleGenerator;
+import org.eclipse.jetty.http.CapsuleParser;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpHeaderValue;
 import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.DatagramChannelEndPoint;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.ManagedSelector;
 import org.eclipse.jetty.io.MappedByteBufferPool;
+import org.eclipse.jetty.io.RuntimeIOException;
 import org.eclipse.jetty.io.SelectorManager;
 import org.eclipse.jetty.io.SocketChannelEndPoint;
 import org.eclipse.jetty.server.Handler;
@@ -47,9 +56,12 @@ import org.eclipse.jetty.server.HttpChannel;
 import org.eclipse.jetty.server.HttpTransport;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.HostPort;
 import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.SocketAddressResolver;
+import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
 import org.eclipse.jetty.util.thread.Scheduler;
 import org.slf4j.Logger;
@@ -57,20 +69,44 @@ import org.slf4j.LoggerFactory;
 
 /**
  * <p>Implementation of a {@link Handler} that supports HTTP CONNECT.</p>
+ * <p>Tunnels are established directly to the target server, or through an
+ * {@link #setUpstreamProxy(UpstreamProxy) upstream proxy}, and a
+ * {@link #setTunnelPolicy(TunnelPolicy) tunnel policy} may decide, for each
+ * CONNECT request, whether the tunnel is allowed and how it is established.</p>
+ * <p>When {@link #setConnectUDPEnabled(boolean) enabled}, UDP flows are proxied as specified by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc9298">RFC 9298</a>: extended CONNECT requests
+ * with the {@code connect-udp} protocol, received over HTTP/2 or HTTP/3, open a UDP socket towards
+ * the target specified by the {@code /.well-known/masque/udp/{target_host}/{target_port}/} path,
+ * and UDP payloads are exchanged as {@code DATAGRAM} capsules in the content of the tunnel.</p>
  */
 public class ConnectHandler extends HandlerWrapper
 {
     protected static final Logger LOG = LoggerFactory.getLogger(ConnectHandler.class);
+    /**
+     * The extended CONNECT protocol used to proxy UDP.
+     */
+    public static final String CONNECT_UDP_PROTOCOL = "connect-udp";
+    /**
+     * The path prefix of the default URI template of {@code connect-udp} requests.
+     */
+    public static final String CONNECT_UDP_PATH_PREFIX = "/.well-known/masque/udp/";
+    private static final String CAPSULE_PROTOCOL = "Capsule-Protocol";
 
     private final Set<String> whiteList = new HashSet<>();
     private final Set<String> blackList = new HashSet<>();
+    private final Set<String> udpWhiteList = new HashSet<>();
     private Executor executor;
     private Scheduler scheduler;
     private ByteBufferPool bufferPool;
     private SelectorManager selector;
+    private SocketAddressResolver socketAddressResolver;
     private long connectTimeout = 15000;
     private long idleTimeout = 30000;
     private int bufferSize = 4096;
+    private UpstreamProxy upstreamProxy;
+    private TunnelPolicy tunnelPolicy;
+    private boolean connectUDPEnabled;
+    private int maxDatagramSize = 65527;
 
     public ConnectHandler()
     {
@@ -114,6 +150,25 @@ public class ConnectHandler extends HandlerWrapper
         this.bufferPool = bufferPool;
     }
 
+    public SocketAddressResolver getSocketAddressResolver()
+    {
+        return socketAddressResolver;
+    }
+
+    /**
+     * <p>Sets the resolver used to resolve asynchronously the host of the CONNECT request,
+     * for example {@code org.eclipse.jetty.io.dns.DnsResolver}.</p>
+     * <p>When a resolver is set, {@link #newConnectAddress(String, int)} is not called
+     * and the first address returned by the resolver is connected.</p>
+     *
+     * @param resolver the resolver, or {@code null} to resolve the host with {@link #newConnectAddress(String, int)}
+     */
+    public void setSocketAddressResolver(SocketAddressResolver resolver)
+    {
+        updateBean(this.socketAddressResolver, resolver);
+        this.socketAddressResolver = resolver;
+    }
+
     /**
      * @return the timeout, in milliseconds, to connect to the remote server
      */
@@ -156,6 +211,78 @@ public class ConnectHandler extends HandlerWrapper
         this.bufferSize = bufferSize;
     }
 
+    /**
+     * @return the upstream proxy through which tunnels are established, or null to establish tunnels directly
+     */
+    public UpstreamProxy getUpstreamProxy()
+    {
+        return upstreamProxy;
+    }
+
+    /**
+     * @param upstreamProxy the upstream proxy through which tunnels are established, or null to establish tunnels directly
+     */
+    public void setUpstreamProxy(UpstreamProxy upstreamProxy)
+    {
+        this.upstreamProxy = upstreamProxy;
+    }
+
+    /**
+     * @return the policy that decides, for each CONNECT request, whether and how the tunnel is established, or null
+     */
+    public TunnelPolicy getTunnelPolicy()
+    {
+        return tunnelPolicy;
+    }
+
+    /**
+     * @param tunnelPolicy the policy that decides, for each CONNECT request, whether and how the tunnel is established,
+     * or null to allow all the tunnels to destinations that are {@link #validateDestination(String, int) valid}
+     */
+    public void setTunnelPolicy(TunnelPolicy tunnelPolicy)
+    {
+        this.tunnelPolicy = tunnelPolicy;
+    }
+
+    /**
+     * @return whether {@code connect-udp} extended CONNECT requests are handled
+     */
+    public boolean isConnectUDPEnabled()
+    {
+        return connectUDPEnabled;
+    }
+
+    /**
+     * <p>Sets whether {@code connect-udp} extended CONNECT requests are handled.</p>
+     * <p>UDP proxying is disabled by default; when enabled, the UDP targets should be
+     * restricted with {@link #getUDPWhiteListHosts()} or with the {@link #setTunnelPolicy(TunnelPolicy) tunnel policy}.</p>
+     *
+     * @param connectUDPEnabled whether {@code connect-udp} extended CONNECT requests are handled
+     */
+    public void setConnectUDPEnabled(boolean connectUDPEnabled)
+    {
+        this.connectUDPEnabled = connectUDPEnabled;
+    }
+
+    /**
+     * @return the max UDP payload size, in bytes, proxied to the client
+     */
+    public int getMaxDatagramSize()
+    {
+        return maxDatagramSize;
+    }
+
+    /**
+     * <p>Sets the max UDP payload size proxied to the client.</p>
+     * <p>UDP payloads received from the target that are larger than this value are truncated.</p>
+     *
+     * @param maxDatagramSize the max UDP payload size, in bytes
+     */
+    public void setMaxDatagramSize(int maxDatagramSize)
+    {
+        this.maxDatagramSize = maxDatagramSize;
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -198,6 +325,12 @@ public class ConnectHandler extends HandlerWrapper
                 LOG.debug("CONNECT request for {}", serverAddress);
             handleConnect(jettyRequest, request, response, serverAddress);
         }
+        else if (HttpMethod.CONNECT.is(request.getMethod()) && CONNECT_UDP_PROTOCOL.equalsIgnoreCase(tunnelProtocol) && isConnectUDPEnabled())
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("CONNECT-UDP request for {}", jettyRequest.getHttpURI());
+            handleConnectUDP(jettyRequest, request, response);
+        }
         else
         {
             super.handle(target, jettyRequest, request, response);
@@ -240,6 +373,28 @@ public class ConnectHandler extends HandlerWrapper
                 return;
             }
 
+            TunnelPolicy.Decision decision = decide(request, host, port);
+            if (decision.getAction() == TunnelPolicy.Decision.Action.DENY)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Tunnel to {}:{} denied by {}", host, port, getTunnelPolicy());
+                sendConnectResponse(request, response, decision.getStatus());
+                return;
+            }
+            UpstreamProxy upstreamProxy;
+            switch (decision.getAction())
+            {
+                case DIRECT:
+                    upstreamProxy = null;
+                    break;
+                case CHAIN:
+                    upstreamProxy = decision.getUpstreamProxy();
+                    break;
+                default:
+                    upstreamProxy = getUpstreamProxy();
+                    break;
+            }
+
             HttpChannel httpChannel = baseRequest.getHttpChannel();
             if (!httpChannel.isTunnellingSupported())
             {
@@ -253,9 +408,9 @@ public class ConnectHandler extends HandlerWrapper
             asyncContext.setTimeout(0);
 
             if (LOG.isDebugEnabled())
-                LOG.debug("Connecting to {}:{}", host, port);
+                LOG.debug("Connecting to {}:{} via {}", host, port, upstreamProxy);
 
-            connectToServer(request, host, port, new Promise<>()
+            Promise<SocketChannel> promise = new Promise<>()
             {
                 @Override
                 public void succeeded(SocketChannel channel)
@@ -272,7 +427,11 @@ public class ConnectHandler extends HandlerWrapper
                 {
                     onConnectFailure(request, response, asyncContext, x);
                 }
-            });
+            };
+            if (upstreamProxy == null)
+                connectToServer(request, host, port, promise);
+            else
+                connectToUpstreamProxy(request, upstreamProxy, host, port, promise);
         }
         catch (Exception x)
         {
@@ -280,7 +439,247 @@ public class ConnectHandler extends HandlerWrapper
         }
     }
 
+    /**
+     * <p>Handles a {@code connect-udp} extended CONNECT request.</p>
+     * <p>The request is authenticated with {@link #handleAuthentication(HttpServletRequest, HttpServletResponse, String)},
+     * the target is checked with {@link #validateUDPDestination(String, int)} and with the
+     * {@link #getTunnelPolicy() tunnel policy}, and then a UDP socket connected to the target is opened.</p>
+     * <p>UDP tunnels cannot be established through upstream proxies, so a {@link TunnelPolicy.Decision.Action#CHAIN}
+     * decision denies the tunnel.</p>
+     *
+     * @param baseRequest Jetty-specific http request
+     * @param request the http request
+     * @param response the http response
+     */
+    protected void handleConnectUDP(Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+    {
+        baseRequest.setHandled(true);
+        try
+        {
+            HostPort target = parseConnectUDPTarget(baseRequest.getHttpURI().getPath());
+            if (target == null)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Invalid CONNECT-UDP target {}", baseRequest.getHttpURI());
+                sendConnectResponse(request, response, HttpServletResponse.SC_BAD_REQUEST);
+                return;
+            }
+            String host = target.getHost();
+            int port = target.getPort();
+
+            boolean proceed = handleAuthentication(request, response, target.toString());
+            if (!proceed)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Missing proxy authentication");
+                sendConnectResponse(request, response, HttpServletResponse.SC_PROXY_AUTHENTICATION_REQUIRED);
+                return;
+            }
+
+            if (!validateUDPDestination(host, port))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("UDP destination {}:{} forbidden", host, port);
+                sendConnectResponse(request, response, HttpServletResponse.SC_FORBIDDEN);
+                return;
+            }
+
+            TunnelPolicy.Decision decision = decide(request, host, port);
+            switch (decision.getAction())
+            {
+                case DENY:
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("UDP tunnel to {}:{} denied by {}", host, port, getTunnelPolicy());
+                    sendConnectResponse(request, response, decision.getStatus());
+                    return;
+                case CHAIN:
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("UDP tunnel to {}:{} cannot be chained via {}", host, port, decision.getUpstreamProxy());
+                    sendConnectResponse(request, response, HttpServletResponse.SC_FORBIDDEN);
+                    return;
+                default:
+                    break;
+            }
+
+            HttpChannel httpChannel = baseRequest.getHttpChannel();
+            if (!httpChannel.isTunnellingSupported())
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("CONNECT-UDP not supported for {}", httpChannel);
+                sendConnectResponse(request, response, HttpServletResponse.SC_FORBIDDEN);
+                return;
+            }
+
+            AsyncContext asyncContext = request.startAsync();
+            asyncContext.setTimeout(0);
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Opening UDP socket to {}:{}", host, port);
+
+            Promise<DatagramChannel> promise = new Promise<>()
+            {
+                @Override
+                public void succeeded(DatagramChannel channel)
+                {
+                    selector.accept(channel, new ConnectContext(request, response, asyncContext, httpChannel.getTunnellingEndPoint()));
+                }
+
+                @Override
+                public void failed(Throwable x)
+                {
+                    onConnectFailure(request, response, asyncContext, x);
+                }
+            };
+            connectToUDPServer(request, host, port, promise);
+        }
+        catch (Exception x)
+        {
+            onConnectFailure(request, response, null, x);
+        }
+    }
+
+    /**
+     * <p>Parses the target of a {@code connect-udp} request from the request path,
+     * that must match the default URI template
+     * {@code /.well-known/masque/udp/{target_host}/{target_port}/}.</p>
+     *
+     * @param path the request path, not decoded
+     * @return the target host and port, or null if the path does not match the URI template
+     */
+    protected HostPort parseConnectUDPTarget(String path)
+    {
+        if (path == null || !path.startsWith(CONNECT_UDP_PATH_PREFIX))
+            return null;
+        String[] segments = path.substring(CONNECT_UDP_PATH_PREFIX.length()).split("/", -1);
+        // The URI template ends with a slash, that may be omitted.
+        if (segments.length < 2 || segments.length > 3 || (segments.length == 3 && !segments[2].isEmpty()))
+            return null;
+        try
+        {
+            // IPv6 addresses have their colons percent-encoded.
+            String host = URIUtil.decodePath(segments[0]);
+            if (host.isEmpty())
+                return null;
+            return new HostPort(host, HostPort.parsePort(segments[1]));
+        }
+        catch (IllegalArgumentException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid CONNECT-UDP path {}", path, x);
+            return null;
+        }
+    }
+
+    /**
+     * <p>Opens a non-blocking UDP socket connected to the given target.</p>
+     *
+     * @param request the {@code connect-udp} request
+     * @param host the target host
+     * @param port the target port
+     * @param promise the promise to complete with the connected UDP socket
+     */
+    protected void connectToUDPServer(HttpServletRequest request, String host, int port, Promise<DatagramChannel> promise)
+    {
+        SocketAddressResolver resolver = getSocketAddressResolver();
+        if (resolver == null)
+        {
+            try
+            {
+                connectToUDPServer(newConnectAddress(host, port), promise);
+            }
+            catch (Throwable x)
+            {
+                promise.failed(x);
+            }
+        }
+        else
+        {
+            resolver.resolve(host, port, Promise.from(addresses -> connectToUDPServer(addresses.get(0), promise), promise::failed));
+        }
+    }
+
     protected void connectToServer(HttpServletRequest request, String host, int port, Promise<SocketChannel> promise)
+    {
+        SocketAddressResolver resolver = getSocketAddressResolver();
+        if (resolver == null)
+        {
+            try
+            {
+                connectToServer(newConnectAddress(host, port), promise);
+            }
+            catch (Throwable x)
+            {
+                promise.failed(x);
+            }
+        }
+        else
+        {
+            resolver.resolve(host, port, Promise.from(addresses -> connectToServer(addresses.get(0), promise), promise::failed));
+        }
+    }
+
+    /**
+     * <p>Applies the {@link #getTunnelPolicy() tunnel policy} to the CONNECT request.</p>
+     *
+     * @param request the CONNECT request
+     * @param host the target host
+     * @param port the target port
+     * @return the decision of the tunnel policy, or {@link TunnelPolicy.Decision#allow()} if there is no tunnel policy
+     */
+    protected TunnelPolicy.Decision decide(HttpServletRequest request, String host, int port)
+    {
+        TunnelPolicy policy = getTunnelPolicy();
+        if (policy == null)
+            return TunnelPolicy.Decision.allow();
+        TunnelPolicy.Decision decision = policy.decide(new TunnelPolicy.Target(request, host, port, Instant.now()));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Tunnel policy decision for {}:{}: {}", host, port, decision);
+        return Objects.requireNonNull(decision);
+    }
+
+    /**
+     * <p>Connects to the given upstream proxy and establishes, through it, the tunnel to the given target.</p>
+     * <p>The connection and the tunnel handshake are performed in blocking mode by a thread of the
+     * {@link #getExecutor() executor}, within the {@link #getConnectTimeout() connect timeout}.</p>
+     *
+     * @param request the CONNECT request
+     * @param upstreamProxy the upstream proxy
+     * @param host the target host
+     * @param port the target port
+     * @param promise the promise to complete with the channel connected to the upstream proxy,
+     * with the tunnel to the target established
+     */
+    protected void connectToUpstreamProxy(HttpServletRequest request, UpstreamProxy upstreamProxy, String host, int port, Promise<SocketChannel> promise)
+    {
+        getExecutor().execute(() ->
+        {
+            SocketChannel channel = null;
+            try
+            {
+                channel = SocketChannel.open();
+                Socket socket = channel.socket();
+                socket.setTcpNoDelay(true);
+                int timeout = (int)Math.min(Integer.MAX_VALUE, getConnectTimeout());
+                socket.connect(newConnectAddress(upstreamProxy.getHost(), upstreamProxy.getPort()), timeout);
+                socket.setSoTimeout(timeout);
+                upstreamProxy.tunnel(socket.getInputStream(), socket.getOutputStream(), host, port);
+                socket.setSoTimeout(0);
+                channel.configureBlocking(false);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Tunnel to {}:{} established via {}", host, port, upstreamProxy);
+                promise.succeeded(channel);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not establish tunnel to {}:{} via {}", host, port, upstreamProxy, x);
+                close(channel);
+                promise.failed(x);
+            }
+        });
+    }
+
+    private void connectToServer(InetSocketAddress address, Promise<SocketChannel> promise)
     {
         SocketChannel channel = null;
         try
@@ -288,7 +687,23 @@ public class ConnectHandler extends HandlerWrapper
             channel = SocketChannel.open();
             channel.socket().setTcpNoDelay(true);
             channel.configureBlocking(false);
-            InetSocketAddress address = newConnectAddress(host, port);
+            channel.connect(address);
+            promise.succeeded(channel);
+        }
+        catch (Throwable x)
+        {
+            close(channel);
+            promise.failed(x);
+        }
+    }
+
+    private void connectToUDPServer(InetSocketAddress address, Promise<DatagramChannel> promise)
+    {
+        DatagramChannel channel = null;
+        try
+        {
+            channel = DatagramChannel.open();
+            channel.configureBlocking(false);
             channel.connect(address);
             promise.succeeded(channel);
         }
@@ -331,7 +746,10 @@ public class ConnectHandler extends HandlerWrapper
         prepareContext(request, context);
 
         EndPoint downstreamEndPoint = connectContext.getEndPoint();
-        DownstreamConnection downstreamConnection = newDownstreamConnection(downstreamEndPoint, context);
+        boolean udp = upstreamConnection instanceof UDPUpstreamConnection;
+        DownstreamConnection downstreamConnection = udp
+            ? newUDPDownstreamConnection(downstreamEndPoint, context)
+            : newDownstreamConnection(downstreamEndPoint, context);
         downstreamConnection.setInputBufferSize(getBufferSize());
 
         upstreamConnection.setConnection(downstreamConnection);
@@ -340,6 +758,8 @@ public class ConnectHandler extends HandlerWrapper
             LOG.debug("Connection setup completed: {}<->{}", downstreamConnection, upstreamConnection);
 
         HttpServletResponse response = connectContext.getResponse();
+        if (udp)
+            response.setHeader(CAPSULE_PROTOCOL, "?1");
         sendConnectResponse(request, response, HttpServletResponse.SC_OK);
 
         upgradeConnection(request, response, downstreamConnection);
@@ -398,6 +818,16 @@ public class ConnectHandler extends HandlerWrapper
         return new UpstreamConnection(endPoint, getExecutor(), getByteBufferPool(), connectContext);
     }
 
+    protected UDPDownstreamConnection newUDPDownstreamConnection(EndPoint endPoint, ConcurrentMap<String, Object> context)
+    {
+        return new UDPDownstreamConnection(endPoint, getExecutor(), getByteBufferPool(), context);
+    }
+
+    protected UDPUpstreamConnection newUDPUpstreamConnection(DatagramChannelEndPoint endPoint, ConnectContext connectContext)
+    {
+        return new UDPUpstreamConnection(endPoint, getExecutor(), getByteBufferPool(), connectContext);
+    }
+
     protected void prepareContext(HttpServletRequest request, ConcurrentMap<String, Object> context)
     {
     }
@@ -454,6 +884,15 @@ public class ConnectHandler extends HandlerWrapper
         return blackList;
     }
 
+    /**
+     * @return the {@code host:port} UDP targets allowed for {@code connect-udp} requests,
+     * or an empty set to allow all the UDP targets
+     */
+    public Set<String> getUDPWhiteListHosts()
+    {
+        return udpWhiteList;
+    }
+
     /**
      * Checks the given {@code host} and {@code port} against whitelist and blacklist.
      *
@@ -485,6 +924,38 @@ public class ConnectHandler extends HandlerWrapper
         return true;
     }
 
+    /**
+     * <p>Checks the given UDP target {@code host} and {@code port} against the UDP whitelist
+     * and against the blacklist, that applies to both TCP and UDP targets.</p>
+     *
+     * @param host the host to check
+     * @param port the port to check
+     * @return true if it is allowed to send UDP datagrams to the given host and port
+     */
+    public boolean validateUDPDestination(String host, int port)
+    {
+        String hostPort = host + ":" + port;
+        if (!udpWhiteList.isEmpty())
+        {
+            if (!udpWhiteList.contains(hostPort))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("UDP host {}:{} not whitelisted", host, port);
+                return false;
+            }
+        }
+        if (!blackList.isEmpty())
+        {
+            if (blackList.contains(hostPort))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("UDP host {}:{} blacklisted", host, port);
+                return false;
+            }
+        }
+        return true;
+    }
+
     protected class ConnectManager extends SelectorManager
     {
         protected ConnectManager(Executor executor, Scheduler scheduler, int selectors)
@@ -495,6 +966,12 @@ public class ConnectHandler extends HandlerWrapper
         @Override
         protected EndPoint newEndPoint(SelectableChannel channel, ManagedSelector selector, SelectionKey key)
         {
+            if (channel instanceof DatagramChannel)
+            {
+                DatagramChannelEndPoint endPoint = new DatagramChannelEndPoint((DatagramChannel)channel, selector, key, getScheduler());
+                endPoint.setIdleTimeout(getIdleTimeout());
+                return endPoint;
+            }
             SocketChannelEndPoint endPoint = new SocketChannelEndPoint((SocketChannel)channel, selector, key, getScheduler());
             endPoint.setIdleTimeout(getIdleTimeout());
             return endPoint;
@@ -503,9 +980,18 @@ public class ConnectHandler extends HandlerWrapper
         @Override
         public Connection newConnection(SelectableChannel channel, EndPoint endpoint, Object attachment) throws IOException
         {
+            ConnectContext connectContext = (ConnectContext)attachment;
+            if (channel instanceof DatagramChannel)
+            {
+                if (ConnectHandler.LOG.isDebugEnabled())
+                    ConnectHandler.LOG.debug("Connected UDP to {}", ((DatagramChannel)channel).getRemoteAddress());
+                UDPUpstreamConnection connection = newUDPUpstreamConnection((DatagramChannelEndPoint)endpoint, connectContext);
+                // Room for the capsule header of the largest UDP payload.
+                connection.setInputBufferSize(getMaxDatagramSize() + 16);
+                return connection;
+            }
             if (ConnectHandler.LOG.isDebugEnabled())
                 ConnectHandler.LOG.debug("Connected to {}", ((SocketChannel)channel).getRemoteAddress());
-            ConnectContext connectContext = (ConnectContext)attachment;
             UpstreamConnection connection = newUpstreamConnection(endpoint, connectContext);
             connection.setInputBufferSize(getBufferSize());
             return connection;
@@ -678,4 +1164,127 @@ public class ConnectHandler extends HandlerWrapper
             ConnectHandler.this.write(endPoint, buffer, callback, getContext());
         }
     }
+
+    /**
+     * <p>The connection on the UDP socket of a {@code connect-udp} tunnel, that forwards
+     * each UDP payload received from the target to the client as a {@code DATAGRAM} capsule.</p>
+     */
+    public class UDPUpstreamConnection extends UpstreamConnection
+    {
+        public UDPUpstreamConnection(DatagramChannelEndPoint endPoint, Executor executor, ByteBufferPool bufferPool, ConnectContext connectContext)
+        {
+            super(endPoint, executor, bufferPool, connectContext);
+        }
+
+        @Override
+        protected int read(EndPoint endPoint, ByteBuffer buffer) throws IOException
+        {
+            ByteBuffer payload = getByteBufferPool().acquire(getMaxDatagramSize(), true);
+            try
+            {
+                SocketAddress peer = ((DatagramChannelEndPoint)endPoint).receive(payload);
+                if (peer == null)
+                    return 0;
+                if (peer == DatagramChannelEndPoint.EOF)
+                    return -1;
+                ByteBuffer capsule = CapsuleGenerator.generateDatagram(0, payload);
+                int length = capsule.remaining();
+                if (length > BufferUtil.space(buffer))
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("{} dropped UDP payload of {} bytes", this, payload.remaining());
+                    return 0;
+                }
+                BufferUtil.append(buffer, capsule);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} received UDP payload of {} bytes", this, payload.remaining());
+                return length;
+            }
+            finally
+            {
+                getByteBufferPool().release(payload);
+            }
+        }
+
+        @Override
+        public void onClose(Throwable cause)
+        {
+            super.onClose(cause);
+            // UDP has no end of stream, so the tunnel
+            // ends when the UDP socket is closed, for
+            // example when it has been idle for too long.
+            Connection connection = getConnection();
+            if (connection != null)
+                connection.getEndPoint().shutdownOutput();
+        }
+    }
+
+    /**
+     * <p>The connection on the tunnel of a {@code connect-udp} request, that parses the
+     * {@code DATAGRAM} capsules sent by the client and sends their UDP payload to the target.</p>
+     */
+    public class UDPDownstreamConnection extends DownstreamConnection
+    {
+        private final CapsuleParser parser = new CapsuleParser(this::onCapsule, getMaxDatagramSize() + 16);
+
+        public UDPDownstreamConnection(EndPoint endPoint, Executor executor, ByteBufferPool bufferPool, ConcurrentMap<String, Object> context)
+        {
+            super(endPoint, executor, bufferPool, context);
+        }
+
+        @Override
+        protected int read(EndPoint endPoint, ByteBuffer buffer) throws IOException
+        {
+            int read = super.read(endPoint, buffer);
+            if (read < 0)
+            {
+                if (!parser.isComplete() && LOG.isDebugEnabled())
+                    LOG.debug("{} truncated capsule", this);
+                // The client closed the tunnel, close the UDP socket.
+                getConnection().close();
+            }
+            return read;
+        }
+
+        @Override
+        protected void write(EndPoint endPoint, ByteBuffer buffer, Callback callback)
+        {
+            try
+            {
+                parser.parse(buffer);
+                callback.succeeded();
+            }
+            catch (Throwable x)
+            {
+                callback.failed(x);
+            }
+        }
+
+        private void onCapsule(long type, ByteBuffer value)
+        {
+            // Unknown capsule types are ignored, as specified by RFC 9297.
+            if (type != CapsuleParser.DATAGRAM)
+                return;
+            long contextId = CapsuleParser.decodeVarInt(value);
+            if (contextId != 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} dropped datagram with unknown context ID {}", this, contextId);
+                return;
+            }
+            DatagramChannelEndPoint endPoint = (DatagramChannelEndPoint)getConnection().getEndPoint();
+            try
+            {
+                int length = value.remaining();
+                // UDP is unreliable, so payloads that cannot be sent immediately are dropped.
+                boolean sent = endPoint.send(endPoint.getChannel().getRemoteAddress(), value);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} {} UDP payload of {} bytes", this, sent ? "sent" : "dropped", length);
+            }
+            catch (IOException x)
+            {
+                throw new RuntimeIOException(x);
+            }
+        }
+    }
 }
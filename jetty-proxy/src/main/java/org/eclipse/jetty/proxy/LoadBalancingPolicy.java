@@ -0,0 +1,122 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.util.List;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.atomic.AtomicLong;
+import javax.servlet.http.HttpServletRequest;
+
+/**
+ * <p>Selects the {@link Backend} that {@link BalancerServlet} proxies a request to.</p>
+ */
+@FunctionalInterface
+public interface LoadBalancingPolicy
+{
+    /**
+     * @param backends the available backends, never empty
+     * @param request the client request
+     * @return the backend to proxy the request to
+     */
+    Backend select(List<Backend> backends, HttpServletRequest request);
+
+    /**
+     * @param name the policy name, one of {@code roundRobin}, {@code leastConnections} or {@code ewma}
+     * @return a new policy for the given name
+     * @throws IllegalArgumentException if the name is unknown
+     */
+    static LoadBalancingPolicy from(String name)
+    {
+        switch (name)
+        {
+            case "roundRobin":
+                return new RoundRobin();
+            case "leastConnections":
+                return new LeastConnections();
+            case "ewma":
+                return new EWMALatency();
+            default:
+                throw new IllegalArgumentException("Unknown load balancing policy " + name);
+        }
+    }
+
+    /**
+     * <p>Selects the backends in turn.</p>
+     */
+    class RoundRobin implements LoadBalancingPolicy
+    {
+        private final AtomicLong counter = new AtomicLong();
+
+        @Override
+        public Backend select(List<Backend> backends, HttpServletRequest request)
+        {
+            return backends.get((int)Math.floorMod(counter.getAndIncrement(), (long)backends.size()));
+        }
+    }
+
+    /**
+     * <p>Selects the backend with the least active requests,
+     * selecting in turn among the backends with the same number.</p>
+     */
+    class LeastConnections implements LoadBalancingPolicy
+    {
+        private final AtomicLong counter = new AtomicLong();
+
+        @Override
+        public Backend select(List<Backend> backends, HttpServletRequest request)
+        {
+            int size = backends.size();
+            int start = (int)Math.floorMod(counter.getAndIncrement(), (long)size);
+            Backend result = null;
+            for (int i = 0; i < size; ++i)
+            {
+                Backend backend = backends.get((start + i) % size);
+                if (result == null || backend.getActiveRequests() < result.getActiveRequests())
+                    result = backend;
+            }
+            return result;
+        }
+    }
+
+    /**
+     * <p>Selects, between two random backends, the one with the lower cost,
+     * where the cost is the {@link Backend#getLatency() latency} weighted by
+     * the number of active requests.</p>
+     * <p>Backends without latency samples yet have no cost, so that they are
+     * selected until their latency is known.</p>
+     */
+    class EWMALatency implements LoadBalancingPolicy
+    {
+        @Override
+        public Backend select(List<Backend> backends, HttpServletRequest request)
+        {
+            int size = backends.size();
+            if (size == 1)
+                return backends.get(0);
+            ThreadLocalRandom random = ThreadLocalRandom.current();
+            int index1 = random.nextInt(size);
+            int index2 = random.nextInt(size - 1);
+            if (index2 >= index1)
+                ++index2;
+            Backend backend1 = backends.get(index1);
+            Backend backend2 = backends.get(index2);
+            return cost(backend2) < cost(backend1) ? backend2 : backend1;
+        }
+
+        private static double cost(Backend backend)
+        {
+            return backend.getLatency() * (backend.getActiveRequests() + 1);
+        }
+    }
+}
@@ -0,0 +1,510 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.EnumSet;
+import java.util.Enumeration;
+import java.util.List;
+import java.util.Set;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.util.BytesRequestContent;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.pathmap.PathSpecSet;
+import org.eclipse.jetty.server.HttpInput;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.IncludeExcludeSet;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that duplicates a sample of the requests it handles to a shadow server.</p>
+ * <p>Mirrored requests have the same method, path, query, headers and content of the
+ * original requests, and are sent with {@link HttpClient} to the {@link #setMirrorURI(String) mirror URI}
+ * once the original request is complete; the responses of the shadow server are ignored.</p>
+ * <p>Requests are mirrored when they match the {@link #includePath(String) path} and
+ * {@link #includeMethod(String) method} rules, and are sampled with the configured
+ * {@link #setPercentage(double) percentage}.
+ * The request content is captured as the application reads it: requests whose content
+ * exceeds {@link #setMaxContentSize(int)}, or whose content is not fully read by the
+ * application, are not mirrored.</p>
+ * <p>To never delay or fail the original requests, mirrored requests are sent from
+ * the {@link HttpClient} executor, at most {@link #setMaxConcurrentMirrors(int)} at a time
+ * (requests in excess are dropped), and failures to mirror are only counted.</p>
+ */
+@ManagedObject("Request mirroring handler")
+public class MirrorHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(MirrorHandler.class);
+    private static final Set<HttpHeader> HOP_HEADERS = EnumSet.of(
+        HttpHeader.CONNECTION,
+        HttpHeader.KEEP_ALIVE,
+        HttpHeader.PROXY_AUTHORIZATION,
+        HttpHeader.PROXY_AUTHENTICATE,
+        HttpHeader.PROXY_CONNECTION,
+        HttpHeader.TE,
+        HttpHeader.TRAILER,
+        HttpHeader.TRANSFER_ENCODING,
+        HttpHeader.UPGRADE,
+        HttpHeader.HOST,
+        HttpHeader.CONTENT_LENGTH,
+        HttpHeader.EXPECT
+    );
+
+    private final IncludeExcludeSet<String, String> paths = new IncludeExcludeSet<>(PathSpecSet.class);
+    private final IncludeExclude<String> methods = new IncludeExclude<>();
+    private final AtomicInteger pending = new AtomicInteger();
+    private final LongAdder mirrored = new LongAdder();
+    private final LongAdder failed = new LongAdder();
+    private final LongAdder dropped = new LongAdder();
+    private HttpClient httpClient;
+    private URI mirrorURI;
+    private double percentage = 100;
+    private int maxContentSize = 64 * 1024;
+    private int maxConcurrentMirrors = 64;
+    private long timeout = 5000;
+    private String mirrorHeader = "X-Mirrored-From";
+
+    public MirrorHandler()
+    {
+        this(null);
+    }
+
+    public MirrorHandler(HttpClient httpClient)
+    {
+        setHttpClient(httpClient);
+    }
+
+    public HttpClient getHttpClient()
+    {
+        return httpClient;
+    }
+
+    /**
+     * @param httpClient the HttpClient used to send the mirrored requests;
+     * if {@code null} a default HttpClient is created when this handler is started
+     */
+    public void setHttpClient(HttpClient httpClient)
+    {
+        updateBean(this.httpClient, httpClient);
+        this.httpClient = httpClient;
+    }
+
+    @ManagedAttribute("The URI of the shadow server")
+    public String getMirrorURI()
+    {
+        return mirrorURI == null ? null : mirrorURI.toString();
+    }
+
+    /**
+     * @param mirrorURI the URI of the shadow server, for example {@code http://shadow:8080};
+     * the original request path and query are appended to the URI path, if any
+     */
+    public void setMirrorURI(String mirrorURI)
+    {
+        this.mirrorURI = mirrorURI == null ? null : URI.create(mirrorURI);
+    }
+
+    @ManagedAttribute("The percentage of matching requests that are mirrored")
+    public double getPercentage()
+    {
+        return percentage;
+    }
+
+    /**
+     * @param percentage the percentage, between 0 and 100, of matching requests that are mirrored
+     */
+    public void setPercentage(double percentage)
+    {
+        if (percentage < 0 || percentage > 100)
+            throw new IllegalArgumentException("Invalid percentage: " + percentage);
+        this.percentage = percentage;
+    }
+
+    @ManagedAttribute("The max request content size of mirrored requests")
+    public int getMaxContentSize()
+    {
+        return maxContentSize;
+    }
+
+    /**
+     * @param maxContentSize the max request content size of mirrored requests
+     */
+    public void setMaxContentSize(int maxContentSize)
+    {
+        this.maxContentSize = maxContentSize;
+    }
+
+    @ManagedAttribute("The max number of mirrored requests in progress")
+    public int getMaxConcurrentMirrors()
+    {
+        return maxConcurrentMirrors;
+    }
+
+    /**
+     * @param maxConcurrentMirrors the max number of mirrored requests in progress,
+     * requests to mirror in excess are dropped
+     */
+    public void setMaxConcurrentMirrors(int maxConcurrentMirrors)
+    {
+        this.maxConcurrentMirrors = maxConcurrentMirrors;
+    }
+
+    @ManagedAttribute("The total timeout in milliseconds of mirrored requests")
+    public long getTimeout()
+    {
+        return timeout;
+    }
+
+    /**
+     * @param timeout the total timeout in milliseconds of mirrored requests
+     */
+    public void setTimeout(long timeout)
+    {
+        this.timeout = timeout;
+    }
+
+    @ManagedAttribute("The header added to mirrored requests")
+    public String getMirrorHeader()
+    {
+        return mirrorHeader;
+    }
+
+    /**
+     * @param mirrorHeader the name of the header, whose value is the original
+     * {@code Host}, added to mirrored requests, or {@code null} for no header
+     */
+    public void setMirrorHeader(String mirrorHeader)
+    {
+        this.mirrorHeader = mirrorHeader;
+    }
+
+    /**
+     * @param pathSpec the path spec of the requests to mirror
+     */
+    public void includePath(String pathSpec)
+    {
+        paths.include(pathSpec);
+    }
+
+    /**
+     * @param pathSpec the path spec of the requests not to mirror
+     */
+    public void excludePath(String pathSpec)
+    {
+        paths.exclude(pathSpec);
+    }
+
+    /**
+     * @param method the method of the requests to mirror
+     */
+    public void includeMethod(String method)
+    {
+        methods.include(method);
+    }
+
+    /**
+     * @param method the method of the requests not to mirror
+     */
+    public void excludeMethod(String method)
+    {
+        methods.exclude(method);
+    }
+
+    @ManagedAttribute("The number of mirrored requests")
+    public long getMirrored()
+    {
+        return mirrored.longValue();
+    }
+
+    @ManagedAttribute("The number of mirrored requests that failed")
+    public long getFailed()
+    {
+        return failed.longValue();
+    }
+
+    @ManagedAttribute("The number of requests not mirrored because of the content size or the concurrency limit")
+    public long getDropped()
+    {
+        return dropped.longValue();
+    }
+
+    @ManagedAttribute("The number of mirrored requests in progress")
+    public int getPending()
+    {
+        return pending.get();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (mirrorURI == null)
+            throw new IllegalStateException("No mirror URI configured");
+        if (httpClient == null)
+            setHttpClient(new HttpClient());
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, org.eclipse.jetty.server.Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        Capture capture = null;
+        try
+        {
+            if (shouldMirror(target, request))
+            {
+                capture = new Capture(request);
+                if (capture.expectsContent)
+                {
+                    long contentLength = request.getContentLengthLong();
+                    if (contentLength > maxContentSize)
+                    {
+                        dropped.increment();
+                        capture = null;
+                    }
+                    else
+                    {
+                        baseRequest.getHttpInput().addInterceptor(capture);
+                    }
+                }
+            }
+        }
+        catch (Throwable x)
+        {
+            LOG.debug("Unable to mirror {}", request, x);
+            capture = null;
+        }
+
+        try
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (capture != null)
+            {
+                if (request.isAsyncStarted())
+                    request.getAsyncContext().addListener(capture);
+                else
+                    mirror(capture);
+            }
+        }
+    }
+
+    /**
+     * <p>Returns whether the given request should be mirrored.</p>
+     * <p>By default, requests that match the path and method rules are sampled
+     * with the configured percentage; CONNECT and upgrade requests are never mirrored.</p>
+     *
+     * @param target the request target
+     * @param request the request
+     * @return whether the request should be mirrored
+     */
+    protected boolean shouldMirror(String target, HttpServletRequest request)
+    {
+        if (HttpMethod.CONNECT.is(request.getMethod()) || request.getHeader(HttpHeader.UPGRADE.asString()) != null)
+            return false;
+        if (!paths.test(target) || !methods.test(request.getMethod()))
+            return false;
+        return percentage >= 100 || ThreadLocalRandom.current().nextDouble(100) < percentage;
+    }
+
+    private void mirror(Capture capture)
+    {
+        if (!capture.isComplete())
+        {
+            dropped.increment();
+            return;
+        }
+        if (pending.incrementAndGet() > maxConcurrentMirrors)
+        {
+            pending.decrementAndGet();
+            dropped.increment();
+            return;
+        }
+        try
+        {
+            httpClient.getExecutor().execute(() -> send(capture));
+        }
+        catch (Throwable x)
+        {
+            pending.decrementAndGet();
+            failed.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Unable to mirror {}", capture.uri, x);
+        }
+    }
+
+    private String mirrorPath(String uri)
+    {
+        String prefix = mirrorURI.getRawPath();
+        if (prefix == null || prefix.isEmpty() || "/".equals(prefix))
+            return uri;
+        return prefix.endsWith("/") ? prefix + uri.substring(1) : prefix + uri;
+    }
+
+    private void send(Capture capture)
+    {
+        try
+        {
+            Request request = httpClient.newRequest(mirrorURI)
+                .method(capture.method)
+                .path(mirrorPath(capture.uri))
+                .timeout(timeout, TimeUnit.MILLISECONDS);
+            request.headers(headers ->
+            {
+                headers.remove(HttpHeader.USER_AGENT);
+                headers.remove(HttpHeader.ACCEPT_ENCODING);
+                for (String[] header : capture.headers)
+                {
+                    headers.add(header[0], header[1]);
+                }
+                if (mirrorHeader != null && capture.host != null)
+                    headers.put(mirrorHeader, capture.host);
+            });
+            if (capture.content != null)
+                request.body(new BytesRequestContent(capture.contentType, capture.content.toByteArray()));
+            mirrored.increment();
+            request.send(result ->
+            {
+                pending.decrementAndGet();
+                if (result.isFailed())
+                {
+                    failed.increment();
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Failed mirror {}", capture.uri, result.getFailure());
+                }
+            });
+        }
+        catch (Throwable x)
+        {
+            pending.decrementAndGet();
+            failed.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Unable to mirror {}", capture.uri, x);
+        }
+    }
+
+    /**
+     * <p>The snapshot of a request to mirror, whose content is captured as the application reads it.</p>
+     */
+    private class Capture implements HttpInput.Interceptor, AsyncListener
+    {
+        private final String method;
+        private final String uri;
+        private final String host;
+        private final String contentType;
+        private final List<String[]> headers = new ArrayList<>();
+        private final boolean expectsContent;
+        private ByteArrayOutputStream content;
+        private boolean eof;
+        private boolean overflow;
+
+        private Capture(HttpServletRequest request)
+        {
+            method = request.getMethod();
+            String query = request.getQueryString();
+            uri = query == null ? request.getRequestURI() : request.getRequestURI() + "?" + query;
+            host = request.getHeader(HttpHeader.HOST.asString());
+            contentType = request.getContentType();
+            for (Enumeration<String> names = request.getHeaderNames(); names.hasMoreElements(); )
+            {
+                String name = names.nextElement();
+                HttpHeader header = HttpHeader.CACHE.get(name);
+                if (header != null && HOP_HEADERS.contains(header))
+                    continue;
+                for (Enumeration<String> values = request.getHeaders(name); values.hasMoreElements(); )
+                {
+                    headers.add(new String[]{name, values.nextElement()});
+                }
+            }
+            expectsContent = request.getContentLengthLong() > 0 || request.getHeader(HttpHeader.TRANSFER_ENCODING.asString()) != null;
+            if (expectsContent)
+                content = new ByteArrayOutputStream();
+        }
+
+        private boolean isComplete()
+        {
+            return !overflow && (!expectsContent || eof);
+        }
+
+        @Override
+        public HttpInput.Content readFrom(HttpInput.Content input)
+        {
+            if (input.isSpecial())
+            {
+                if (input.isEof())
+                    eof = true;
+                return input;
+            }
+            if (!overflow)
+            {
+                ByteBuffer buffer = input.getByteBuffer().slice();
+                if (content.size() + buffer.remaining() > maxContentSize)
+                {
+                    overflow = true;
+                    content = null;
+                }
+                else
+                {
+                    byte[] bytes = new byte[buffer.remaining()];
+                    buffer.get(bytes);
+                    content.writeBytes(bytes);
+                }
+            }
+            return input;
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            mirror(this);
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+    }
+}
@@ -16,11 +16,13 @@ package org.eclipse.jetty.proxy;
 import java.io.IOException;
 import java.io.InputStream;
 import java.nio.ByteBuffer;
+import java.nio.file.Path;
 import java.util.concurrent.Executor;
 import java.util.concurrent.TimeUnit;
 import javax.servlet.AsyncContext;
 import javax.servlet.ServletConfig;
 import javax.servlet.ServletException;
+import javax.servlet.ServletOutputStream;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
@@ -30,10 +32,22 @@ import org.eclipse.jetty.client.api.Result;
 import org.eclipse.jetty.client.util.AsyncRequestContent;
 import org.eclipse.jetty.client.util.InputStreamRequestContent;
 import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IteratingCallback;
 
 /**
  * <p>Servlet 3.0 asynchronous proxy servlet.</p>
  * <p>The request processing is asynchronous, but the I/O is blocking.</p>
+ * <p>Responses can be spooled with a {@link ResponseSpooler}, so that the content
+ * of large responses to slow clients does not hold the connection to the server;
+ * spooling is enabled with the {@code spooling} init parameter, and configured
+ * with the following init parameters:</p>
+ * <ul>
+ * <li>spoolDirectory - the directory of the spool files, by default the {@code java.io.tmpdir} directory</li>
+ * <li>spoolMemoryThreshold - the bytes of a response kept in memory before spooling to disk</li>
+ * <li>spoolMaxMemorySize - the max bytes kept in memory by all the responses</li>
+ * <li>spoolMaxDiskSize - the max bytes written to disk by all the responses</li>
+ * <li>spoolMaxResponseSize - the max bytes spooled for a single response</li>
+ * </ul>
  *
  * @see AsyncProxyServlet
  * @see AsyncMiddleManServlet
@@ -43,6 +57,68 @@ public class ProxyServlet extends AbstractProxyServlet
 {
     private static final String CONTINUE_ACTION_ATTRIBUTE = ProxyServlet.class.getName() + ".continueAction";
 
+    private ResponseSpooler _responseSpooler;
+
+    @Override
+    public void init() throws ServletException
+    {
+        super.init();
+        ServletConfig config = getServletConfig();
+        if (Boolean.parseBoolean(config.getInitParameter("spooling")))
+        {
+            _responseSpooler = createResponseSpooler();
+            // Put the spooler in the context to leverage ContextHandler.MANAGED_ATTRIBUTES
+            getServletContext().setAttribute(config.getServletName() + ".ResponseSpooler", _responseSpooler);
+        }
+    }
+
+    /**
+     * @return a {@link ResponseSpooler} configured from the {@link #getServletConfig() servlet configuration}
+     */
+    protected ResponseSpooler createResponseSpooler()
+    {
+        ServletConfig config = getServletConfig();
+        ResponseSpooler spooler = new ResponseSpooler(getHttpClient().getByteBufferPool());
+
+        String value = config.getInitParameter("spoolDirectory");
+        if (value != null)
+            spooler.setDirectory(Path.of(value));
+
+        value = config.getInitParameter("spoolMemoryThreshold");
+        if (value != null)
+            spooler.setMemoryThreshold(Integer.parseInt(value));
+
+        value = config.getInitParameter("spoolMaxMemorySize");
+        if (value != null)
+            spooler.setMaxMemorySize(Long.parseLong(value));
+
+        value = config.getInitParameter("spoolMaxDiskSize");
+        if (value != null)
+            spooler.setMaxDiskSize(Long.parseLong(value));
+
+        value = config.getInitParameter("spoolMaxResponseSize");
+        if (value != null)
+            spooler.setMaxResponseSize(Long.parseLong(value));
+
+        return spooler;
+    }
+
+    /**
+     * @return the spooler of the responses, or null if responses are not spooled
+     */
+    public ResponseSpooler getResponseSpooler()
+    {
+        return _responseSpooler;
+    }
+
+    /**
+     * @param responseSpooler the spooler of the responses, or null to not spool responses
+     */
+    public void setResponseSpooler(ResponseSpooler responseSpooler)
+    {
+        _responseSpooler = responseSpooler;
+    }
+
     @Override
     protected void service(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
     {
@@ -69,12 +145,14 @@ public class ProxyServlet extends AbstractProxyServlet
 
         copyRequestHeaders(request, proxyRequest);
 
+        copyRequestTrailers(request, proxyRequest);
+
         addProxyHeaders(request, proxyRequest);
 
         AsyncContext asyncContext = request.startAsync();
         // We do not timeout the continuation, but the proxy request
         asyncContext.setTimeout(0);
-        proxyRequest.timeout(getTimeout(), TimeUnit.MILLISECONDS);
+        proxyRequest.timeout(getTimeout(request), TimeUnit.MILLISECONDS);
 
         if (hasContent(request))
         {
@@ -132,7 +210,11 @@ public class ProxyServlet extends AbstractProxyServlet
         {
             if (_log.isDebugEnabled())
                 _log.debug("{} proxying content to downstream: {} bytes", getRequestId(request), length);
-            response.getOutputStream().write(buffer, offset, length);
+            ServletOutputStream output = response.getOutputStream();
+            output.write(buffer, offset, length);
+            // gRPC messages must not be held in the aggregation buffer.
+            if (isGrpcRequest(request))
+                output.flush();
             callback.succeeded();
         }
         catch (Throwable x)
@@ -141,6 +223,28 @@ public class ProxyServlet extends AbstractProxyServlet
         }
     }
 
+    /**
+     * <p>Returns the spool that buffers the content of the given server response;
+     * the content of the server response is written to the client as it is read
+     * from the spool, so that the server response completes without waiting
+     * for the client.</p>
+     * <p>By default, all the responses but gRPC ones are spooled by the
+     * {@link #getResponseSpooler() response spooler}, if any; applications may
+     * override this method to spool only some responses, or to apply a per-request
+     * limit with {@link ResponseSpooler#newSpool(long)}.</p>
+     *
+     * @param clientRequest the client-to-proxy request
+     * @param serverResponse the server-to-proxy response, after the headers have been received
+     * @return the spool of the response, or null to not spool the response
+     */
+    protected ResponseSpooler.Spool newResponseSpool(HttpServletRequest clientRequest, Response serverResponse)
+    {
+        ResponseSpooler spooler = getResponseSpooler();
+        if (spooler == null || isGrpcRequest(clientRequest))
+            return null;
+        return spooler.newSpool();
+    }
+
     @Override
     protected void onContinue(HttpServletRequest clientRequest, Request proxyRequest)
     {
@@ -177,6 +281,8 @@ public class ProxyServlet extends AbstractProxyServlet
     {
         private final HttpServletRequest request;
         private final HttpServletResponse response;
+        private ResponseSpooler.Spool spool;
+        private SpoolWriter spoolWriter;
 
         protected ProxyResponseListener(HttpServletRequest request, HttpServletResponse response)
         {
@@ -194,11 +300,31 @@ public class ProxyServlet extends AbstractProxyServlet
         public void onHeaders(Response proxyResponse)
         {
             onServerResponseHeaders(request, response, proxyResponse);
+            spool = newResponseSpool(request, proxyResponse);
+            if (spool != null)
+                spoolWriter = new SpoolWriter(request, response, proxyResponse, spool);
         }
 
         @Override
         public void onContent(Response proxyResponse, ByteBuffer content, Callback callback)
         {
+            if (spool != null)
+            {
+                if (_log.isDebugEnabled())
+                    _log.debug("{} spooling content: {} bytes", getRequestId(request), content.remaining());
+                spool.append(content, new Callback.Nested(callback)
+                {
+                    @Override
+                    public void failed(Throwable x)
+                    {
+                        super.failed(x);
+                        proxyResponse.abort(x);
+                    }
+                });
+                spoolWriter.iterateLater();
+                return;
+            }
+
             byte[] buffer;
             int offset;
             int length = content.remaining();
@@ -228,6 +354,19 @@ public class ProxyServlet extends AbstractProxyServlet
         @Override
         public void onComplete(Result result)
         {
+            if (spool != null)
+            {
+                // The spool writer completes the proxy response once the spool is drained.
+                if (result.isSucceeded())
+                    spool.complete();
+                else
+                    spool.fail(result.getFailure());
+                spoolWriter.iterateLater();
+                if (_log.isDebugEnabled())
+                    _log.debug("{} proxying complete, spooled {}", getRequestId(request), spool);
+                return;
+            }
+
             if (result.isSucceeded())
                 onProxyResponseSuccess(request, response, result.getResponse());
             else
@@ -237,6 +376,56 @@ public class ProxyServlet extends AbstractProxyServlet
         }
     }
 
+    private class SpoolWriter extends IteratingCallback
+    {
+        private final byte[] buffer = new byte[getHttpClient().getResponseBufferSize()];
+        private final HttpServletRequest request;
+        private final HttpServletResponse response;
+        private final Response proxyResponse;
+        private final ResponseSpooler.Spool spool;
+
+        private SpoolWriter(HttpServletRequest request, HttpServletResponse response, Response proxyResponse, ResponseSpooler.Spool spool)
+        {
+            this.request = request;
+            this.response = response;
+            this.proxyResponse = proxyResponse;
+            this.spool = spool;
+        }
+
+        private void iterateLater()
+        {
+            // Writes to the client may block, and must not hold the thread reading from the server.
+            getHttpClient().getExecutor().execute(this::iterate);
+        }
+
+        @Override
+        protected Action process() throws Throwable
+        {
+            int read = spool.read(buffer);
+            if (read > 0)
+            {
+                onResponseContent(request, response, proxyResponse, buffer, 0, read, this);
+                return Action.SCHEDULED;
+            }
+            return spool.isDrained() ? Action.SUCCEEDED : Action.IDLE;
+        }
+
+        @Override
+        protected void onCompleteSuccess()
+        {
+            spool.release();
+            onProxyResponseSuccess(request, response, proxyResponse);
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable cause)
+        {
+            spool.release();
+            proxyResponse.abort(cause);
+            onProxyResponseFailure(request, response, proxyResponse, cause);
+        }
+    }
+
     protected class ProxyInputStreamRequestContent extends InputStreamRequestContent
     {
         private final HttpServletResponse response;
@@ -0,0 +1,555 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.Deque;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.Atomics;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.statistic.CounterStatistic;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Buffers the content of proxied responses, so that the connection to the server
+ * can be released at the server speed rather than at the speed of slow clients.</p>
+ * <p>Each response is buffered by a {@link Spool}: the content is first kept in memory,
+ * up to {@link #setMemoryThreshold(int)} bytes per response, and then is written in
+ * chunks to a temporary file in the {@link #setDirectory(Path) spool directory}.
+ * The memory and disk space used by all the spools are bounded by
+ * {@link #setMaxMemorySize(long)} and {@link #setMaxDiskSize(long)}, while
+ * {@link #setMaxResponseSize(long)} bounds what a single response may spool.</p>
+ * <p>Content that does not fit the limits is not lost: it is queued without being
+ * copied, and the server is only allowed to send more content when the client has
+ * read it, as when spooling is disabled.</p>
+ */
+@ManagedObject("Spool of proxied responses")
+public class ResponseSpooler
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResponseSpooler.class);
+
+    private final CounterStatistic spools = new CounterStatistic();
+    private final AtomicLong memorySize = new AtomicLong();
+    private final AtomicLong memorySizeMax = new AtomicLong();
+    private final AtomicLong diskSize = new AtomicLong();
+    private final AtomicLong diskSizeMax = new AtomicLong();
+    private final LongAdder spooledBytes = new LongAdder();
+    private final LongAdder overflows = new LongAdder();
+    private final ByteBufferPool byteBufferPool;
+    private Path directory = Path.of(System.getProperty("java.io.tmpdir"));
+    private int memoryThreshold = 64 * 1024;
+    private long maxMemorySize = 64 * 1024 * 1024;
+    private long maxDiskSize = 1024 * 1024 * 1024;
+    private long maxResponseSize = 256 * 1024 * 1024;
+
+    /**
+     * @param byteBufferPool the pool of the buffers that hold the content kept in memory
+     */
+    public ResponseSpooler(ByteBufferPool byteBufferPool)
+    {
+        this.byteBufferPool = Objects.requireNonNull(byteBufferPool);
+    }
+
+    @ManagedAttribute("The directory of the spool files")
+    public Path getDirectory()
+    {
+        return directory;
+    }
+
+    /**
+     * @param directory the directory where the temporary spool files are created
+     */
+    public void setDirectory(Path directory)
+    {
+        this.directory = Objects.requireNonNull(directory);
+    }
+
+    @ManagedAttribute("The bytes of a response kept in memory before spooling to disk")
+    public int getMemoryThreshold()
+    {
+        return memoryThreshold;
+    }
+
+    /**
+     * @param memoryThreshold the bytes of a response kept in memory before switching to a spool file
+     */
+    public void setMemoryThreshold(int memoryThreshold)
+    {
+        this.memoryThreshold = memoryThreshold;
+    }
+
+    @ManagedAttribute("The max bytes kept in memory by all the spools")
+    public long getMaxMemorySize()
+    {
+        return maxMemorySize;
+    }
+
+    /**
+     * @param maxMemorySize the max bytes kept in memory by all the spools, or -1 for no limit
+     */
+    public void setMaxMemorySize(long maxMemorySize)
+    {
+        this.maxMemorySize = maxMemorySize;
+    }
+
+    @ManagedAttribute("The max bytes written to disk by all the spools")
+    public long getMaxDiskSize()
+    {
+        return maxDiskSize;
+    }
+
+    /**
+     * @param maxDiskSize the max bytes written to the spool files of all the spools, or -1 for no limit
+     */
+    public void setMaxDiskSize(long maxDiskSize)
+    {
+        this.maxDiskSize = maxDiskSize;
+    }
+
+    @ManagedAttribute("The max bytes spooled for a single response")
+    public long getMaxResponseSize()
+    {
+        return maxResponseSize;
+    }
+
+    /**
+     * @param maxResponseSize the default max bytes spooled for a single response, or -1 for no limit
+     * @see #newSpool(long)
+     */
+    public void setMaxResponseSize(long maxResponseSize)
+    {
+        this.maxResponseSize = maxResponseSize;
+    }
+
+    @ManagedAttribute("The number of responses being spooled")
+    public long getSpools()
+    {
+        return spools.getCurrent();
+    }
+
+    @ManagedAttribute("The max number of responses spooled at the same time")
+    public long getSpoolsMax()
+    {
+        return spools.getMax();
+    }
+
+    @ManagedAttribute("The total number of spooled responses")
+    public long getSpoolsTotal()
+    {
+        return spools.getTotal();
+    }
+
+    @ManagedAttribute("The bytes currently kept in memory")
+    public long getMemorySize()
+    {
+        return memorySize.get();
+    }
+
+    @ManagedAttribute("The max bytes kept in memory at the same time")
+    public long getMemorySizeMax()
+    {
+        return memorySizeMax.get();
+    }
+
+    @ManagedAttribute("The bytes currently written to spool files")
+    public long getDiskSize()
+    {
+        return diskSize.get();
+    }
+
+    @ManagedAttribute("The max bytes written to spool files at the same time")
+    public long getDiskSizeMax()
+    {
+        return diskSizeMax.get();
+    }
+
+    @ManagedAttribute("The total bytes spooled in memory or on disk")
+    public long getSpooledBytes()
+    {
+        return spooledBytes.longValue();
+    }
+
+    @ManagedAttribute("The number of times content was not spooled because a limit was reached")
+    public long getOverflows()
+    {
+        return overflows.longValue();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        spools.reset(spools.getCurrent());
+        memorySizeMax.set(memorySize.get());
+        diskSizeMax.set(diskSize.get());
+        spooledBytes.reset();
+        overflows.reset();
+    }
+
+    /**
+     * @return a new spool limited to {@link #getMaxResponseSize()} bytes
+     */
+    public Spool newSpool()
+    {
+        return newSpool(getMaxResponseSize());
+    }
+
+    /**
+     * @param maxSize the max bytes spooled for the response, or -1 for no limit
+     * @return a new spool for the content of a response
+     */
+    public Spool newSpool(long maxSize)
+    {
+        spools.increment();
+        return new Spool(maxSize);
+    }
+
+    private boolean reserve(AtomicLong size, AtomicLong sizeMax, long max, long amount)
+    {
+        while (true)
+        {
+            long current = size.get();
+            long updated = current + amount;
+            if (max >= 0 && updated > max)
+                return false;
+            if (size.compareAndSet(current, updated))
+            {
+                Atomics.updateMax(sizeMax, updated);
+                return true;
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[spools=%d,memory=%d,disk=%d]", getClass().getSimpleName(), hashCode(), getSpools(), getMemorySize(), getDiskSize());
+    }
+
+    /**
+     * <p>The content of a single response, read back in the order it was appended.</p>
+     */
+    public class Spool
+    {
+        private final AutoLock lock = new AutoLock();
+        private final Deque<Chunk> chunks = new ArrayDeque<>();
+        private final long maxSize;
+        private long size;
+        private long memory;
+        private long disk;
+        private FileChannel file;
+        private boolean complete;
+        private boolean released;
+        private Throwable failure;
+
+        private Spool(long maxSize)
+        {
+            this.maxSize = maxSize;
+        }
+
+        /**
+         * <p>Appends content to this spool.</p>
+         * <p>The callback is succeeded immediately if the content could be copied in memory
+         * or to the spool file; otherwise the content is queued as is, and the callback is
+         * succeeded once the content has been {@link #read(byte[]) read}.</p>
+         *
+         * @param content the content to append
+         * @param callback the callback to complete when the content buffer can be reused
+         */
+        public void append(ByteBuffer content, Callback callback)
+        {
+            int length = content.remaining();
+            Throwable failed;
+            boolean spooled = false;
+            try (AutoLock l = lock.lock())
+            {
+                failed = failure;
+                if (failed == null)
+                {
+                    if (maxSize < 0 || size + length <= maxSize)
+                        spooled = spoolToMemory(content) || spoolToDisk(content);
+                    if (spooled)
+                        size += length;
+                    else
+                        chunks.offer(new Chunk(content, callback));
+                }
+            }
+
+            if (failed != null)
+            {
+                callback.failed(failed);
+            }
+            else if (spooled)
+            {
+                spooledBytes.add(length);
+                callback.succeeded();
+            }
+            else
+            {
+                overflows.increment();
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Spool overflow of {} bytes on {}", length, this);
+            }
+        }
+
+        private boolean spoolToMemory(ByteBuffer content)
+        {
+            assert lock.isHeldByCurrentThread();
+            int length = content.remaining();
+            if (memory + length > getMemoryThreshold())
+                return false;
+            if (!reserve(memorySize, memorySizeMax, getMaxMemorySize(), length))
+                return false;
+            ByteBuffer buffer = byteBufferPool.acquire(length, true);
+            BufferUtil.append(buffer, content);
+            memory += length;
+            chunks.offer(new Chunk(buffer));
+            return true;
+        }
+
+        private boolean spoolToDisk(ByteBuffer content)
+        {
+            assert lock.isHeldByCurrentThread();
+            int length = content.remaining();
+            if (!reserve(diskSize, diskSizeMax, getMaxDiskSize(), length))
+                return false;
+            try
+            {
+                if (file == null)
+                {
+                    Path path = Files.createTempFile(getDirectory(), "proxy-spool-", ".tmp");
+                    file = FileChannel.open(path, StandardOpenOption.READ, StandardOpenOption.WRITE, StandardOpenOption.DELETE_ON_CLOSE);
+                }
+                long position = file.size();
+                ByteBuffer slice = content.slice();
+                while (slice.hasRemaining())
+                {
+                    position += file.write(slice, position);
+                }
+                content.position(content.limit());
+                disk += length;
+                chunks.offer(new Chunk(position - length, length));
+                return true;
+            }
+            catch (IOException x)
+            {
+                diskSize.addAndGet(-length);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not spool to disk {}", this, x);
+                return false;
+            }
+        }
+
+        /**
+         * <p>Reads spooled content into the given array.</p>
+         *
+         * @param buffer the array to fill
+         * @return the number of bytes read, or 0 if no content is available
+         * @throws IOException if this spool failed or the spool file cannot be read
+         */
+        public int read(byte[] buffer) throws IOException
+        {
+            Callback callback = null;
+            int read;
+            try (AutoLock l = lock.lock())
+            {
+                if (failure != null)
+                    throw failure instanceof IOException ? (IOException)failure : new IOException(failure);
+                Chunk chunk = chunks.peek();
+                if (chunk == null)
+                    return 0;
+                read = chunk.read(buffer);
+                if (chunk.isConsumed())
+                {
+                    chunks.poll();
+                    callback = consumed(chunk);
+                }
+            }
+            if (callback != null)
+                callback.succeeded();
+            return read;
+        }
+
+        private Callback consumed(Chunk chunk)
+        {
+            assert lock.isHeldByCurrentThread();
+            if (chunk.buffer != null && chunk.callback == null)
+            {
+                int length = chunk.length;
+                memory -= length;
+                memorySize.addAndGet(-length);
+                byteBufferPool.release(chunk.buffer);
+            }
+            return chunk.callback;
+        }
+
+        /**
+         * <p>Marks the end of the content.</p>
+         */
+        public void complete()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                complete = true;
+            }
+        }
+
+        /**
+         * @return whether the end of the content has been appended and all the content has been read
+         */
+        public boolean isDrained()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                return complete && chunks.isEmpty();
+            }
+        }
+
+        /**
+         * <p>Fails this spool, discarding the content not read yet; subsequent
+         * reads throw, and the queued content callbacks are failed.</p>
+         *
+         * @param x the failure
+         */
+        public void fail(Throwable x)
+        {
+            List<Callback> callbacks = new ArrayList<>();
+            try (AutoLock l = lock.lock())
+            {
+                if (failure == null)
+                    failure = x;
+                Chunk chunk;
+                while ((chunk = chunks.poll()) != null)
+                {
+                    Callback callback = consumed(chunk);
+                    if (callback != null)
+                        callbacks.add(callback);
+                }
+            }
+            callbacks.forEach(callback -> callback.failed(x));
+        }
+
+        /**
+         * <p>Releases the memory and the spool file of this spool.</p>
+         */
+        public void release()
+        {
+            fail(new IOException("Spool released"));
+            try (AutoLock l = lock.lock())
+            {
+                if (released)
+                    return;
+                released = true;
+                diskSize.addAndGet(-disk);
+                disk = 0;
+                if (file != null)
+                {
+                    try
+                    {
+                        file.close();
+                    }
+                    catch (IOException x)
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Could not close spool file {}", this, x);
+                    }
+                }
+            }
+            spools.decrement();
+        }
+
+        @Override
+        public String toString()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                return String.format("%s@%x[size=%d,memory=%d,disk=%d,chunks=%d,complete=%b]", getClass().getSimpleName(), hashCode(), size, memory, disk, chunks.size(), complete);
+            }
+        }
+
+        private class Chunk
+        {
+            private final ByteBuffer buffer;
+            private final Callback callback;
+            private final int length;
+            private long position;
+            private int remaining;
+
+            private Chunk(ByteBuffer buffer)
+            {
+                this(buffer, null);
+            }
+
+            private Chunk(ByteBuffer buffer, Callback callback)
+            {
+                this.buffer = buffer;
+                this.callback = callback;
+                this.length = buffer.remaining();
+                this.remaining = length;
+            }
+
+            private Chunk(long position, int length)
+            {
+                this.buffer = null;
+                this.callback = null;
+                this.length = length;
+                this.position = position;
+                this.remaining = length;
+            }
+
+            private int read(byte[] bytes) throws IOException
+            {
+                int read = Math.min(bytes.length, remaining);
+                if (buffer != null)
+                {
+                    buffer.get(bytes, 0, read);
+                }
+                else
+                {
+                    ByteBuffer target = ByteBuffer.wrap(bytes, 0, read);
+                    while (target.hasRemaining())
+                    {
+                        if (file.read(target, position + target.position()) < 0)
+                            throw new IOException("Truncated spool file");
+                    }
+                    position += read;
+                }
+                remaining -= read;
+                return read;
+            }
+
+            private boolean isConsumed()
+            {
+                return remaining == 0;
+            }
+        }
+    }
+}
@@ -0,0 +1,233 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.security.Principal;
+import java.util.Objects;
+
+import org.eclipse.jetty.io.EndPoint;
+
+/**
+ * <p>A SOCKS5 authentication method, on the server side.</p>
+ * <p>After the authentication method has been selected, the
+ * {@link Socks5ServerConnectionFactory SOCKS5 server} feeds the bytes received
+ * from the client to a {@link Negotiation}, and sends to the client the replies
+ * produced by the negotiation, until the negotiation succeeds or fails.</p>
+ * <p>Implementations may support, for example, the GSSAPI method
+ * (<a href="https://datatracker.ietf.org/doc/html/rfc1961">RFC 1961</a>)
+ * by exchanging the tokens of a {@code org.ietf.jgss.GSSContext};
+ * note however that the SOCKS5 server only tunnels the bytes after the
+ * authentication, so methods that require per-message encapsulation
+ * of the tunnelled bytes are not supported.</p>
+ *
+ * @see UsernamePassword
+ */
+public interface Socks5ServerAuthenticator
+{
+    /**
+     * @return the SOCKS5 authentication method of this authenticator
+     */
+    public byte getMethod();
+
+    /**
+     * @param endPoint the endPoint of the client to authenticate
+     * @return a new negotiation for the given client
+     */
+    public Negotiation newNegotiation(EndPoint endPoint);
+
+    /**
+     * <p>The authentication exchanges with a client.</p>
+     */
+    public interface Negotiation
+    {
+        /**
+         * <p>Consumes the bytes of an authentication message from the given buffer.</p>
+         * <p>If the buffer does not contain a whole message, no bytes must be consumed
+         * and null must be returned, so that this method is called again when more
+         * bytes have been received.</p>
+         *
+         * @param buffer the bytes received from the client
+         * @return the step of the negotiation, or null if more bytes are needed
+         * @throws IOException if the message is invalid
+         */
+        public Step negotiate(ByteBuffer buffer) throws IOException;
+    }
+
+    /**
+     * <p>A step of a {@link Negotiation}, with the reply to send to the client.</p>
+     */
+    public static class Step
+    {
+        /**
+         * @param reply the reply to send to the client
+         * @return a step that sends the reply and waits for the next message
+         */
+        public static Step proceed(ByteBuffer reply)
+        {
+            return new Step(Objects.requireNonNull(reply), false, null);
+        }
+
+        /**
+         * @param reply the reply to send to the client
+         * @param principal the authenticated client
+         * @return a step that sends the reply and completes the authentication successfully
+         */
+        public static Step succeed(ByteBuffer reply, Principal principal)
+        {
+            return new Step(reply, true, Objects.requireNonNull(principal));
+        }
+
+        /**
+         * @param reply the reply to send to the client, or null
+         * @return a step that sends the reply and closes the connection
+         */
+        public static Step fail(ByteBuffer reply)
+        {
+            return new Step(reply, true, null);
+        }
+
+        private final ByteBuffer reply;
+        private final boolean complete;
+        private final Principal principal;
+
+        private Step(ByteBuffer reply, boolean complete, Principal principal)
+        {
+            this.reply = reply;
+            this.complete = complete;
+            this.principal = principal;
+        }
+
+        /**
+         * @return the reply to send to the client, or null
+         */
+        public ByteBuffer getReply()
+        {
+            return reply;
+        }
+
+        /**
+         * @return whether the negotiation is complete
+         */
+        public boolean isComplete()
+        {
+            return complete;
+        }
+
+        /**
+         * @return the authenticated client if the negotiation is complete and succeeded, or null
+         */
+        public Principal getPrincipal()
+        {
+            return principal;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[complete=%b,principal=%s]", getClass().getSimpleName(), hashCode(), complete, principal);
+        }
+    }
+
+    /**
+     * <p>The username/password authentication method, as defined by
+     * <a href="https://datatracker.ietf.org/doc/html/rfc1929">RFC 1929</a>.</p>
+     * <p>The credentials are verified by a {@link Login}, for example:</p>
+     * <pre>{@code
+     * socks5.addAuthenticator(new Socks5ServerAuthenticator.UsernamePassword((username, password) ->
+     * {
+     *     UserIdentity user = loginService.login(username, password, null);
+     *     return user == null ? null : user.getUserPrincipal();
+     * }));
+     * }</pre>
+     */
+    public static class UsernamePassword implements Socks5ServerAuthenticator
+    {
+        public static final byte METHOD = 0x02;
+        private static final byte VERSION = 0x01;
+        private static final byte SUCCESS = 0x00;
+        private static final byte FAILURE = 0x01;
+
+        private final Login login;
+
+        public UsernamePassword(Login login)
+        {
+            this.login = Objects.requireNonNull(login);
+        }
+
+        @Override
+        public byte getMethod()
+        {
+            return METHOD;
+        }
+
+        @Override
+        public Negotiation newNegotiation(EndPoint endPoint)
+        {
+            return buffer ->
+            {
+                // +-----+------+----------+------+----------+
+                // | VER | ULEN |  UNAME   | PLEN |  PASSWD  |
+                // +-----+------+----------+------+----------+
+                int position = buffer.position();
+                if (buffer.remaining() < 2)
+                    return null;
+                if (buffer.get(position) != VERSION)
+                    throw new IOException("Invalid username/password authentication version " + buffer.get(position));
+                int usernameLength = buffer.get(position + 1) & 0xFF;
+                if (buffer.remaining() < 3 + usernameLength)
+                    return null;
+                int passwordLength = buffer.get(position + 2 + usernameLength) & 0xFF;
+                if (buffer.remaining() < 3 + usernameLength + passwordLength)
+                    return null;
+
+                byte[] bytes = new byte[usernameLength];
+                buffer.position(position + 2);
+                buffer.get(bytes);
+                String username = new String(bytes, StandardCharsets.UTF_8);
+                bytes = new byte[passwordLength];
+                buffer.get();
+                buffer.get(bytes);
+                String password = new String(bytes, StandardCharsets.UTF_8);
+
+                Principal principal = login.login(username, password);
+                if (principal == null)
+                    return Step.fail(ByteBuffer.wrap(new byte[]{VERSION, FAILURE}));
+                return Step.succeed(ByteBuffer.wrap(new byte[]{VERSION, SUCCESS}), principal);
+            };
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), login);
+        }
+
+        /**
+         * <p>Verifies the credentials of SOCKS5 clients.</p>
+         */
+        @FunctionalInterface
+        public interface Login
+        {
+            /**
+             * @param username the username sent by the client
+             * @param password the password sent by the client
+             * @return the authenticated client, or null if the credentials are not valid
+             */
+            public Principal login(String username, String password);
+        }
+    }
+}
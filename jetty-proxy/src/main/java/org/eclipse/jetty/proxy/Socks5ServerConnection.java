@@ -0,0 +1,538 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.IOException;
+import java.net.ConnectException;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.net.UnknownHostException;
+import java.nio.ByteBuffer;
+import java.nio.channels.UnresolvedAddressException;
+import java.nio.charset.StandardCharsets;
+import java.security.Principal;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.Executor;
+
+import org.eclipse.jetty.client.Socks5;
+import org.eclipse.jetty.io.AbstractConnection;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.ClientConnectionFactory;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.Promise;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>The server side of a SOCKS5 connection.</p>
+ * <p>This connection negotiates the authentication method with the client,
+ * authenticates the client, reads the client request and connects to the
+ * requested target; then it upgrades to a connection that tunnels bytes
+ * between the client and the target.</p>
+ *
+ * @see Socks5ServerConnectionFactory
+ */
+public class Socks5ServerConnection extends AbstractConnection implements Connection.UpgradeFrom, Connection.UpgradeTo
+{
+    private static final Logger LOG = LoggerFactory.getLogger(Socks5ServerConnection.class);
+    private static final byte NO_ACCEPTABLE_METHODS = (byte)0xFF;
+    private static final byte SUCCEEDED = 0x00;
+    private static final byte GENERAL_FAILURE = 0x01;
+    private static final byte NOT_ALLOWED = 0x02;
+    private static final byte HOST_UNREACHABLE = 0x04;
+    private static final byte CONNECTION_REFUSED = 0x05;
+    private static final byte COMMAND_NOT_SUPPORTED = 0x07;
+    private static final byte ADDRESS_TYPE_NOT_SUPPORTED = 0x08;
+
+    private final ByteBufferPool bufferPool;
+    private final Socks5ServerConnectionFactory factory;
+    private final ByteBuffer buffer;
+    private volatile State state = State.GREETING;
+    private Socks5ServerAuthenticator.Negotiation negotiation;
+    private Principal principal;
+
+    public Socks5ServerConnection(EndPoint endPoint, Executor executor, ByteBufferPool bufferPool, Socks5ServerConnectionFactory factory)
+    {
+        super(endPoint, executor);
+        this.bufferPool = bufferPool;
+        this.factory = factory;
+        this.buffer = BufferUtil.allocate(factory.getInputBufferSize());
+    }
+
+    /**
+     * @return the authenticated client, or null if the client has not been authenticated
+     */
+    public Principal getPrincipal()
+    {
+        return principal;
+    }
+
+    @Override
+    public void onUpgradeTo(ByteBuffer prefilled)
+    {
+        BufferUtil.append(buffer, prefilled);
+    }
+
+    @Override
+    public ByteBuffer onUpgradeFrom()
+    {
+        if (BufferUtil.isEmpty(buffer))
+            return null;
+        ByteBuffer leftover = BufferUtil.copy(buffer);
+        BufferUtil.clear(buffer);
+        return leftover;
+    }
+
+    @Override
+    public void onOpen()
+    {
+        super.onOpen();
+        process();
+    }
+
+    @Override
+    public void onFillable()
+    {
+        process();
+    }
+
+    private void process()
+    {
+        try
+        {
+            while (true)
+            {
+                if (parse())
+                    return;
+
+                if (BufferUtil.space(buffer) == 0)
+                {
+                    BufferUtil.compact(buffer);
+                    if (BufferUtil.space(buffer) == 0)
+                        throw new IOException("SOCKS5 message too large");
+                }
+
+                int filled = getEndPoint().fill(buffer);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} filled {} bytes", this, filled);
+                if (filled < 0)
+                {
+                    getEndPoint().close();
+                    return;
+                }
+                if (filled == 0)
+                {
+                    fillInterested();
+                    return;
+                }
+            }
+        }
+        catch (Throwable x)
+        {
+            onFailure(x);
+        }
+    }
+
+    /**
+     * @return true if a message has been parsed and processing continues asynchronously,
+     * false if more bytes are needed
+     */
+    private boolean parse() throws IOException
+    {
+        switch (state)
+        {
+            case GREETING:
+                return parseGreeting();
+            case AUTHENTICATION:
+                return parseAuthentication();
+            case REQUEST:
+                return parseRequest();
+            default:
+                throw new IllegalStateException("Invalid state " + state);
+        }
+    }
+
+    private boolean parseGreeting() throws IOException
+    {
+        // +-----+----------+----------+
+        // | VER | NMETHODS | METHODS  |
+        // +-----+----------+----------+
+        int position = buffer.position();
+        if (buffer.remaining() < 2)
+            return false;
+        checkVersion(buffer.get(position));
+        int count = buffer.get(position + 1) & 0xFF;
+        if (buffer.remaining() < 2 + count)
+            return false;
+        byte[] methods = new byte[count];
+        buffer.position(position + 2);
+        buffer.get(methods);
+
+        Socks5ServerAuthenticator authenticator = null;
+        byte method = NO_ACCEPTABLE_METHODS;
+        if (factory.getAuthenticators().isEmpty())
+        {
+            if (contains(methods, Socks5.NoAuthenticationFactory.METHOD))
+                method = Socks5.NoAuthenticationFactory.METHOD;
+        }
+        else
+        {
+            for (Socks5ServerAuthenticator candidate : factory.getAuthenticators())
+            {
+                if (contains(methods, candidate.getMethod()))
+                {
+                    authenticator = candidate;
+                    method = candidate.getMethod();
+                    break;
+                }
+            }
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} selected method {} with {}", this, method & 0xFF, authenticator);
+
+        ByteBuffer reply = ByteBuffer.wrap(new byte[]{Socks5.VERSION, method});
+        if (method == NO_ACCEPTABLE_METHODS)
+        {
+            writeAndClose(reply);
+        }
+        else
+        {
+            if (authenticator == null)
+            {
+                state = State.REQUEST;
+            }
+            else
+            {
+                negotiation = authenticator.newNegotiation(getEndPoint());
+                state = State.AUTHENTICATION;
+            }
+            writeAndProcess(reply);
+        }
+        return true;
+    }
+
+    private boolean parseAuthentication() throws IOException
+    {
+        Socks5ServerAuthenticator.Step step = negotiation.negotiate(buffer);
+        if (step == null)
+            return false;
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} authentication {}", this, step);
+
+        if (!step.isComplete())
+        {
+            writeAndProcess(step.getReply());
+        }
+        else if (step.getPrincipal() == null)
+        {
+            if (step.getReply() == null)
+                getEndPoint().close();
+            else
+                writeAndClose(step.getReply());
+        }
+        else
+        {
+            negotiation = null;
+            principal = step.getPrincipal();
+            state = State.REQUEST;
+            writeAndProcess(step.getReply());
+        }
+        return true;
+    }
+
+    private boolean parseRequest() throws IOException
+    {
+        // +-----+-----+-------+------+----------+----------+
+        // | VER | CMD |  RSV  | ATYP | DST.ADDR | DST.PORT |
+        // +-----+-----+-------+------+----------+----------+
+        int position = buffer.position();
+        if (buffer.remaining() < 5)
+            return false;
+        checkVersion(buffer.get(position));
+        byte command = buffer.get(position + 1);
+        byte addressType = buffer.get(position + 3);
+        int addressLength;
+        switch (addressType)
+        {
+            case Socks5.ADDRESS_TYPE_IPV4:
+                addressLength = 4;
+                break;
+            case Socks5.ADDRESS_TYPE_DOMAIN:
+                addressLength = 1 + (buffer.get(position + 4) & 0xFF);
+                break;
+            case Socks5.ADDRESS_TYPE_IPV6:
+                addressLength = 16;
+                break;
+            default:
+                writeAndClose(reply(ADDRESS_TYPE_NOT_SUPPORTED, null));
+                return true;
+        }
+        if (buffer.remaining() < 4 + addressLength + 2)
+            return false;
+
+        buffer.position(position + 4);
+        String host;
+        if (addressType == Socks5.ADDRESS_TYPE_DOMAIN)
+        {
+            byte[] bytes = new byte[buffer.get() & 0xFF];
+            buffer.get(bytes);
+            host = new String(bytes, StandardCharsets.US_ASCII);
+        }
+        else
+        {
+            byte[] bytes = new byte[addressLength];
+            buffer.get(bytes);
+            host = InetAddress.getByAddress(bytes).getHostAddress();
+        }
+        int port = buffer.getShort() & 0xFFFF;
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} request command {} for {}:{}", this, command, host, port);
+
+        if (command != Socks5.COMMAND_CONNECT)
+            writeAndClose(reply(COMMAND_NOT_SUPPORTED, null));
+        else if (!factory.validateDestination(host, port))
+            writeAndClose(reply(NOT_ALLOWED, null));
+        else
+            connect(host, port);
+        return true;
+    }
+
+    private void connect(String host, int port)
+    {
+        state = State.CONNECTING;
+        InetSocketAddress address = factory.newConnectAddress(host, port);
+        if (address.isUnresolved())
+        {
+            writeAndClose(reply(HOST_UNREACHABLE, null));
+            return;
+        }
+
+        ConcurrentMap<String, Object> tunnelContext = new ConcurrentHashMap<>();
+        Map<String, Object> context = new HashMap<>();
+        context.put(ClientConnector.CLIENT_CONNECTION_FACTORY_CONTEXT_KEY, (ClientConnectionFactory)(endPoint, ctx) ->
+        {
+            UpstreamConnection upstream = new UpstreamConnection(endPoint, getExecutor(), bufferPool, tunnelContext);
+            upstream.setInputBufferSize(factory.getBufferSize());
+            return upstream;
+        });
+        context.put(ClientConnector.CONNECTION_PROMISE_CONTEXT_KEY, new Promise<Connection>()
+        {
+            @Override
+            public void succeeded(Connection connection)
+            {
+                onConnected((UpstreamConnection)connection);
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                onConnectFailed(x);
+            }
+        });
+        factory.getClientConnector().connect(address, context);
+    }
+
+    private void onConnected(UpstreamConnection upstream)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} connected to {}", this, upstream);
+
+        if (!getEndPoint().isOpen())
+        {
+            upstream.close();
+            return;
+        }
+
+        ByteBuffer reply = reply(SUCCEEDED, upstream.getEndPoint().getLocalSocketAddress());
+        getEndPoint().write(Callback.from(() -> tunnel(upstream), x ->
+        {
+            upstream.close();
+            onFailure(x);
+        }), reply);
+    }
+
+    private void tunnel(UpstreamConnection upstream)
+    {
+        DownstreamConnection downstream = new DownstreamConnection(getEndPoint(), getExecutor(), bufferPool, upstream.getContext());
+        downstream.setInputBufferSize(factory.getBufferSize());
+        upstream.setConnection(downstream);
+        downstream.setConnection(upstream);
+        state = State.TUNNELLING;
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} tunnelling {} <=> {}", this, downstream, upstream);
+        getEndPoint().upgrade(downstream);
+        upstream.fillInterested();
+    }
+
+    private void onConnectFailed(Throwable failure)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} could not connect", this, failure);
+        byte code = GENERAL_FAILURE;
+        if (failure instanceof UnknownHostException || failure instanceof UnresolvedAddressException)
+            code = HOST_UNREACHABLE;
+        else if (failure instanceof ConnectException)
+            code = CONNECTION_REFUSED;
+        writeAndClose(reply(code, null));
+    }
+
+    private void writeAndProcess(ByteBuffer reply)
+    {
+        getEndPoint().write(Callback.from(this::process, this::onFailure), reply);
+    }
+
+    private void writeAndClose(ByteBuffer reply)
+    {
+        getEndPoint().write(Callback.from(() -> getEndPoint().close(), this::onFailure), reply);
+    }
+
+    private void onFailure(Throwable failure)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} failure", this, failure);
+        getEndPoint().close(failure);
+    }
+
+    private static void checkVersion(byte version) throws IOException
+    {
+        if (version != Socks5.VERSION)
+            throw new IOException("Unsupported SOCKS version " + version);
+    }
+
+    private static boolean contains(byte[] methods, byte method)
+    {
+        for (byte m : methods)
+        {
+            if (m == method)
+                return true;
+        }
+        return false;
+    }
+
+    private static ByteBuffer reply(byte code, SocketAddress bound)
+    {
+        // +-----+-----+-------+------+----------+----------+
+        // | VER | REP |  RSV  | ATYP | BND.ADDR | BND.PORT |
+        // +-----+-----+-------+------+----------+----------+
+        byte[] address = new byte[4];
+        int port = 0;
+        if (bound instanceof InetSocketAddress)
+        {
+            InetSocketAddress inetAddress = (InetSocketAddress)bound;
+            if (inetAddress.getAddress() != null)
+            {
+                address = inetAddress.getAddress().getAddress();
+                port = inetAddress.getPort();
+            }
+        }
+        byte addressType = address.length == 4 ? Socks5.ADDRESS_TYPE_IPV4 : Socks5.ADDRESS_TYPE_IPV6;
+        ByteBuffer reply = ByteBuffer.allocate(6 + address.length);
+        reply.put(Socks5.VERSION).put(code).put(Socks5.RESERVED).put(addressType).put(address).putShort((short)port);
+        reply.flip();
+        return reply;
+    }
+
+    @Override
+    public String toConnectionString()
+    {
+        return String.format("%s@%x[%s,principal=%s]", getClass().getSimpleName(), hashCode(), state, principal);
+    }
+
+    private enum State
+    {
+        GREETING, AUTHENTICATION, REQUEST, CONNECTING, TUNNELLING
+    }
+
+    private static class UpstreamConnection extends ProxyConnection
+    {
+        private UpstreamConnection(EndPoint endPoint, Executor executor, ByteBufferPool bufferPool, ConcurrentMap<String, Object> context)
+        {
+            super(endPoint, executor, bufferPool, context);
+        }
+
+        @Override
+        protected int read(EndPoint endPoint, ByteBuffer buffer) throws IOException
+        {
+            return endPoint.fill(buffer);
+        }
+
+        @Override
+        protected void write(EndPoint endPoint, ByteBuffer buffer, Callback callback)
+        {
+            endPoint.write(callback, buffer);
+        }
+    }
+
+    private static class DownstreamConnection extends ProxyConnection implements Connection.UpgradeTo
+    {
+        private ByteBuffer buffer;
+
+        private DownstreamConnection(EndPoint endPoint, Executor executor, ByteBufferPool bufferPool, ConcurrentMap<String, Object> context)
+        {
+            super(endPoint, executor, bufferPool, context);
+        }
+
+        @Override
+        public void onUpgradeTo(ByteBuffer buffer)
+        {
+            this.buffer = buffer;
+        }
+
+        @Override
+        public void onOpen()
+        {
+            super.onOpen();
+
+            if (buffer == null)
+            {
+                fillInterested();
+                return;
+            }
+
+            write(getConnection().getEndPoint(), buffer, Callback.from(() ->
+            {
+                buffer = null;
+                fillInterested();
+            }, x ->
+            {
+                buffer = null;
+                close();
+                getConnection().close();
+            }));
+        }
+
+        @Override
+        protected int read(EndPoint endPoint, ByteBuffer buffer) throws IOException
+        {
+            return endPoint.fill(buffer);
+        }
+
+        @Override
+        protected void write(EndPoint endPoint, ByteBuffer buffer, Callback callback)
+        {
+            endPoint.write(callback, buffer);
+        }
+    }
+}
@@ -0,0 +1,200 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.net.InetSocketAddress;
+import java.nio.ByteBuffer;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+
+import org.eclipse.jetty.client.Socks5;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.server.AbstractConnectionFactory;
+import org.eclipse.jetty.server.ConnectionFactory;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ConnectionFactory} for the SOCKS5 protocol, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc1928">RFC 1928</a>,
+ * that allows Jetty to act as a SOCKS5 proxy server.</p>
+ * <p>Only the {@code CONNECT} command is supported: once a SOCKS5 client has
+ * negotiated the authentication method and requested the target, a connection
+ * to the target is opened with the {@link #getClientConnector() ClientConnector},
+ * and then bytes are tunnelled between the client and the target, similarly to
+ * what {@link ConnectHandler} does for HTTP {@code CONNECT} requests.</p>
+ * <p>Clients are authenticated by the {@link Socks5ServerAuthenticator}s
+ * {@link #addAuthenticator(Socks5ServerAuthenticator) added} to this factory, in
+ * the order they are added; if no authenticator is added, clients must not
+ * require authentication.
+ * Targets are validated against the {@link #getWhiteListHosts() white list} and
+ * the {@link #getBlackListHosts() black list}, see {@link #validateDestination(String, int)}.</p>
+ * <p>This factory is a {@link ConnectionFactory.Detecting detecting} factory, so that
+ * SOCKS5 and HTTP can be served on the same port by wrapping it in a
+ * {@link org.eclipse.jetty.server.DetectorConnectionFactory}:</p>
+ * <pre>{@code
+ * Socks5ServerConnectionFactory socks5 = new Socks5ServerConnectionFactory();
+ * socks5.getWhiteListHosts().add("backend.example.com:443");
+ * HttpConnectionFactory http = new HttpConnectionFactory();
+ * ServerConnector connector = new ServerConnector(server, new DetectorConnectionFactory(socks5), http);
+ * }</pre>
+ */
+@ManagedObject("SOCKS5 proxy connection factory")
+public class Socks5ServerConnectionFactory extends AbstractConnectionFactory implements ConnectionFactory.Detecting
+{
+    private static final Logger LOG = LoggerFactory.getLogger(Socks5ServerConnectionFactory.class);
+
+    private final List<Socks5ServerAuthenticator> authenticators = new CopyOnWriteArrayList<>();
+    private final Set<String> whiteList = new HashSet<>();
+    private final Set<String> blackList = new HashSet<>();
+    private final ClientConnector clientConnector;
+    private int bufferSize = 4096;
+
+    public Socks5ServerConnectionFactory()
+    {
+        this(new ClientConnector());
+    }
+
+    /**
+     * @param clientConnector the client connector used to connect to the targets
+     */
+    public Socks5ServerConnectionFactory(ClientConnector clientConnector)
+    {
+        super("socks5");
+        this.clientConnector = Objects.requireNonNull(clientConnector);
+        addBean(clientConnector);
+    }
+
+    /**
+     * @return the client connector used to connect to the targets
+     */
+    public ClientConnector getClientConnector()
+    {
+        return clientConnector;
+    }
+
+    /**
+     * @return the authenticators, in order of preference
+     */
+    public List<Socks5ServerAuthenticator> getAuthenticators()
+    {
+        return authenticators;
+    }
+
+    /**
+     * <p>Adds an authenticator; the first authenticator whose method is
+     * offered by the client is used to authenticate the client.</p>
+     *
+     * @param authenticator the authenticator to add
+     */
+    public void addAuthenticator(Socks5ServerAuthenticator authenticator)
+    {
+        authenticators.add(Objects.requireNonNull(authenticator));
+    }
+
+    /**
+     * @return the buffer size used to tunnel bytes between clients and targets
+     */
+    @ManagedAttribute("The buffer size used to tunnel bytes")
+    public int getBufferSize()
+    {
+        return bufferSize;
+    }
+
+    /**
+     * @param bufferSize the buffer size used to tunnel bytes between clients and targets
+     */
+    public void setBufferSize(int bufferSize)
+    {
+        this.bufferSize = bufferSize;
+    }
+
+    /**
+     * @return the targets, in the form {@code host:port}, that clients are allowed to connect to;
+     * if empty, all the targets not in the black list are allowed
+     */
+    public Set<String> getWhiteListHosts()
+    {
+        return whiteList;
+    }
+
+    /**
+     * @return the targets, in the form {@code host:port}, that clients are not allowed to connect to
+     */
+    public Set<String> getBlackListHosts()
+    {
+        return blackList;
+    }
+
+    /**
+     * <p>Checks the given {@code host} and {@code port} against the white list and the black list.</p>
+     * <p>The host is the one requested by the client, either a domain name
+     * or the textual representation of an IP address.</p>
+     *
+     * @param host the host to check
+     * @param port the port to check
+     * @return true if it is allowed to connect to the given host and port
+     */
+    public boolean validateDestination(String host, int port)
+    {
+        String hostPort = host + ":" + port;
+        if (!whiteList.isEmpty() && !whiteList.contains(hostPort))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Host {} not whitelisted", hostPort);
+            return false;
+        }
+        if (blackList.contains(hostPort))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Host {} blacklisted", hostPort);
+            return false;
+        }
+        return true;
+    }
+
+    /**
+     * <p>Creates the address of the target to connect to.</p>
+     *
+     * @param host the host requested by the client
+     * @param port the port requested by the client
+     * @return the address to connect to
+     */
+    protected InetSocketAddress newConnectAddress(String host, int port)
+    {
+        return new InetSocketAddress(host, port);
+    }
+
+    @Override
+    public Detection detect(ByteBuffer buffer)
+    {
+        if (!buffer.hasRemaining())
+            return Detection.NEED_MORE_BYTES;
+        return buffer.get(buffer.position()) == Socks5.VERSION ? Detection.RECOGNIZED : Detection.NOT_RECOGNIZED;
+    }
+
+    @Override
+    public Connection newConnection(Connector connector, EndPoint endPoint)
+    {
+        return configure(new Socks5ServerConnection(endPoint, connector.getExecutor(), connector.getByteBufferPool(), this), connector, endPoint);
+    }
+}
@@ -0,0 +1,250 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.security.Principal;
+import java.time.Instant;
+import java.util.Objects;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+/**
+ * <p>A policy that decides, for each {@code CONNECT} request received by
+ * {@link ConnectHandler}, whether the tunnel is allowed, and how it is
+ * established.</p>
+ * <p>The decision may be based on the {@link Target target} host and port,
+ * on the client identity, such as the client address or the authenticated
+ * user, and on the time of the request, for example:</p>
+ * <pre>{@code
+ * connectHandler.setTunnelPolicy(target ->
+ * {
+ *     if (target.getPort() != 443)
+ *         return TunnelPolicy.Decision.deny();
+ *     if (target.getHost().endsWith(".internal.example.com"))
+ *         return TunnelPolicy.Decision.direct();
+ *     int hour = target.getTime().atZone(ZoneId.systemDefault()).getHour();
+ *     if (target.getUserPrincipal() == null && (hour < 8 || hour >= 18))
+ *         return TunnelPolicy.Decision.deny();
+ *     return TunnelPolicy.Decision.chain(egressProxy);
+ * });
+ * }</pre>
+ * <p>The policy is applied after {@link ConnectHandler#handleAuthentication(HttpServletRequest, HttpServletResponse, String)}
+ * and {@link ConnectHandler#validateDestination(String, int)}.</p>
+ *
+ * @see ConnectHandler#setTunnelPolicy(TunnelPolicy)
+ */
+@FunctionalInterface
+public interface TunnelPolicy
+{
+    /**
+     * @param target the tunnel target
+     * @return the decision for the tunnel, never null
+     */
+    public Decision decide(Target target);
+
+    /**
+     * <p>The target of a {@code CONNECT} request, along with the request information.</p>
+     */
+    public static class Target
+    {
+        private final HttpServletRequest request;
+        private final String host;
+        private final int port;
+        private final Instant time;
+
+        public Target(HttpServletRequest request, String host, int port, Instant time)
+        {
+            this.request = request;
+            this.host = host;
+            this.port = port;
+            this.time = time;
+        }
+
+        /**
+         * @return the {@code CONNECT} request
+         */
+        public HttpServletRequest getRequest()
+        {
+            return request;
+        }
+
+        /**
+         * @return the target host
+         */
+        public String getHost()
+        {
+            return host;
+        }
+
+        /**
+         * @return the target port
+         */
+        public int getPort()
+        {
+            return port;
+        }
+
+        /**
+         * @return the time of the {@code CONNECT} request
+         */
+        public Instant getTime()
+        {
+            return time;
+        }
+
+        /**
+         * @return the client IP address
+         */
+        public String getRemoteAddress()
+        {
+            return request.getRemoteAddr();
+        }
+
+        /**
+         * @return the authenticated client, or null
+         */
+        public Principal getUserPrincipal()
+        {
+            return request.getUserPrincipal();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s:%d from %s]", getClass().getSimpleName(), hashCode(), host, port, getRemoteAddress());
+        }
+    }
+
+    /**
+     * <p>The decision of a {@link TunnelPolicy}.</p>
+     */
+    public static class Decision
+    {
+        private static final Decision ALLOW = new Decision(Action.ALLOW, null, 0);
+        private static final Decision DIRECT = new Decision(Action.DIRECT, null, 0);
+        private static final Decision DENY = new Decision(Action.DENY, null, HttpServletResponse.SC_FORBIDDEN);
+
+        /**
+         * <p>The possible actions of a decision.</p>
+         */
+        public enum Action
+        {
+            /**
+             * The tunnel is allowed, and established as configured
+             * by {@link ConnectHandler#getUpstreamProxy()}.
+             */
+            ALLOW,
+            /**
+             * The tunnel is allowed, and established directly to the target,
+             * even if {@link ConnectHandler#getUpstreamProxy()} is configured.
+             */
+            DIRECT,
+            /**
+             * The tunnel is allowed, and established through the {@link #getUpstreamProxy() upstream proxy}
+             * of the decision.
+             */
+            CHAIN,
+            /**
+             * The tunnel is not allowed, and the {@code CONNECT} request is
+             * responded with the {@link #getStatus() status} of the decision.
+             */
+            DENY
+        }
+
+        /**
+         * @return a decision that allows the tunnel
+         * @see Action#ALLOW
+         */
+        public static Decision allow()
+        {
+            return ALLOW;
+        }
+
+        /**
+         * @return a decision that allows the tunnel directly to the target
+         * @see Action#DIRECT
+         */
+        public static Decision direct()
+        {
+            return DIRECT;
+        }
+
+        /**
+         * @param upstreamProxy the upstream proxy
+         * @return a decision that allows the tunnel through the given upstream proxy
+         * @see Action#CHAIN
+         */
+        public static Decision chain(UpstreamProxy upstreamProxy)
+        {
+            return new Decision(Action.CHAIN, Objects.requireNonNull(upstreamProxy), 0);
+        }
+
+        /**
+         * @return a decision that denies the tunnel with a 403 status
+         * @see Action#DENY
+         */
+        public static Decision deny()
+        {
+            return DENY;
+        }
+
+        /**
+         * @param status the status of the response to the {@code CONNECT} request
+         * @return a decision that denies the tunnel with the given status
+         * @see Action#DENY
+         */
+        public static Decision deny(int status)
+        {
+            return new Decision(Action.DENY, null, status);
+        }
+
+        private final Action action;
+        private final UpstreamProxy upstreamProxy;
+        private final int status;
+
+        private Decision(Action action, UpstreamProxy upstreamProxy, int status)
+        {
+            this.action = action;
+            this.upstreamProxy = upstreamProxy;
+            this.status = status;
+        }
+
+        public Action getAction()
+        {
+            return action;
+        }
+
+        /**
+         * @return the upstream proxy of a {@link Action#CHAIN} decision, or null
+         */
+        public UpstreamProxy getUpstreamProxy()
+        {
+            return upstreamProxy;
+        }
+
+        /**
+         * @return the response status of a {@link Action#DENY} decision
+         */
+        public int getStatus()
+        {
+            return status;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,%s,%d]", getClass().getSimpleName(), hashCode(), action, upstreamProxy, status);
+        }
+    }
+}
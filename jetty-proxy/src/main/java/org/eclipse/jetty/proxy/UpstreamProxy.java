@@ -0,0 +1,264 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.ByteArrayOutputStream;
+import java.io.EOFException;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.net.ProtocolException;
+import java.nio.charset.StandardCharsets;
+import java.util.Base64;
+import java.util.Objects;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>An upstream proxy through which {@link ConnectHandler} establishes tunnels,
+ * for example when Jetty is deployed behind a corporate egress proxy.</p>
+ * <p>The tunnel to the upstream proxy is established either with an
+ * HTTP {@code CONNECT} request, or with a SOCKS5 {@code CONNECT} command,
+ * optionally authenticating with a username and password, using
+ * {@code Proxy-Authorization: Basic} for HTTP and the username/password
+ * method (RFC 1929) for SOCKS5.</p>
+ *
+ * @see ConnectHandler#setUpstreamProxy(UpstreamProxy)
+ * @see TunnelPolicy.Decision#chain(UpstreamProxy)
+ */
+public class UpstreamProxy
+{
+    private static final int SOCKS5_VERSION = 0x05;
+    private static final int SOCKS5_NO_AUTH = 0x00;
+    private static final int SOCKS5_USER_PASS_AUTH = 0x02;
+    private static final int SOCKS5_COMMAND_CONNECT = 0x01;
+    private static final int SOCKS5_ADDRESS_IPV4 = 0x01;
+    private static final int SOCKS5_ADDRESS_DOMAIN = 0x03;
+    private static final int SOCKS5_ADDRESS_IPV6 = 0x04;
+
+    /**
+     * <p>The protocol used to establish tunnels through the upstream proxy.</p>
+     */
+    public enum Type
+    {
+        HTTP, SOCKS5
+    }
+
+    private final Type type;
+    private final String host;
+    private final int port;
+    private final String username;
+    private final String password;
+
+    public UpstreamProxy(@Name("type") Type type, @Name("host") String host, @Name("port") int port)
+    {
+        this(type, host, port, null, null);
+    }
+
+    public UpstreamProxy(@Name("type") Type type, @Name("host") String host, @Name("port") int port, @Name("username") String username, @Name("password") String password)
+    {
+        this.type = Objects.requireNonNull(type);
+        this.host = Objects.requireNonNull(host);
+        this.port = port;
+        this.username = username;
+        this.password = password;
+    }
+
+    public Type getType()
+    {
+        return type;
+    }
+
+    public String getHost()
+    {
+        return host;
+    }
+
+    public int getPort()
+    {
+        return port;
+    }
+
+    public String getUsername()
+    {
+        return username;
+    }
+
+    /**
+     * <p>Establishes the tunnel to the given target through this upstream proxy,
+     * over a connection to this upstream proxy that has just been opened.</p>
+     * <p>Reads from the given input stream do not go past the upstream proxy
+     * reply, so that the tunnelled bytes that may follow are not consumed.</p>
+     *
+     * @param input the input stream of the connection to this upstream proxy
+     * @param output the output stream of the connection to this upstream proxy
+     * @param targetHost the target host
+     * @param targetPort the target port
+     * @throws IOException if the tunnel cannot be established
+     */
+    public void tunnel(InputStream input, OutputStream output, String targetHost, int targetPort) throws IOException
+    {
+        switch (type)
+        {
+            case HTTP:
+                tunnelHTTP(input, output, targetHost, targetPort);
+                break;
+            case SOCKS5:
+                tunnelSOCKS5(input, output, targetHost, targetPort);
+                break;
+            default:
+                throw new IllegalStateException("Unknown upstream proxy type " + type);
+        }
+    }
+
+    private void tunnelHTTP(InputStream input, OutputStream output, String targetHost, int targetPort) throws IOException
+    {
+        String authority = (targetHost.indexOf(':') >= 0 ? "[" + targetHost + "]" : targetHost) + ":" + targetPort;
+        StringBuilder request = new StringBuilder();
+        request.append("CONNECT ").append(authority).append(" HTTP/1.1\r\n");
+        request.append(HttpHeader.HOST.asString()).append(": ").append(authority).append("\r\n");
+        if (username != null)
+        {
+            String credentials = username + ":" + (password == null ? "" : password);
+            String encoded = Base64.getEncoder().encodeToString(credentials.getBytes(StandardCharsets.UTF_8));
+            request.append(HttpHeader.PROXY_AUTHORIZATION.asString()).append(": Basic ").append(encoded).append("\r\n");
+        }
+        request.append("\r\n");
+        output.write(request.toString().getBytes(StandardCharsets.ISO_8859_1));
+        output.flush();
+
+        // Read the response headers byte by byte, to not consume tunnelled bytes.
+        ByteArrayOutputStream headers = new ByteArrayOutputStream();
+        int state = 0;
+        while (state < 4)
+        {
+            int b = input.read();
+            if (b < 0)
+                throw new EOFException("Unexpected EOF from upstream proxy " + this);
+            headers.write(b);
+            if (headers.size() > 8192)
+                throw new ProtocolException("Upstream proxy response headers too large " + this);
+            if ((b == '\r' && (state == 0 || state == 2)) || (b == '\n' && (state == 1 || state == 3)))
+                ++state;
+            else
+                state = b == '\r' ? 1 : 0;
+        }
+
+        String response = headers.toString(StandardCharsets.ISO_8859_1);
+        String statusLine = response.substring(0, response.indexOf('\r'));
+        String[] parts = statusLine.split(" ", 3);
+        int status;
+        try
+        {
+            status = parts.length > 1 && parts[0].startsWith("HTTP/") ? Integer.parseInt(parts[1]) : -1;
+        }
+        catch (NumberFormatException x)
+        {
+            status = -1;
+        }
+        if (!HttpStatus.isSuccess(status))
+            throw new ProtocolException(String.format("Upstream proxy %s refused CONNECT %s: %s", this, authority, statusLine));
+    }
+
+    private void tunnelSOCKS5(InputStream input, OutputStream output, String targetHost, int targetPort) throws IOException
+    {
+        if (username == null)
+            output.write(new byte[]{SOCKS5_VERSION, 1, SOCKS5_NO_AUTH});
+        else
+            output.write(new byte[]{SOCKS5_VERSION, 2, SOCKS5_NO_AUTH, SOCKS5_USER_PASS_AUTH});
+        output.flush();
+
+        byte[] methodReply = readFully(input, 2);
+        if (methodReply[0] != SOCKS5_VERSION)
+            throw new ProtocolException("Invalid SOCKS5 version from upstream proxy " + this);
+        int method = methodReply[1] & 0xFF;
+        if (method == SOCKS5_USER_PASS_AUTH && username != null)
+        {
+            byte[] user = username.getBytes(StandardCharsets.UTF_8);
+            byte[] pass = (password == null ? "" : password).getBytes(StandardCharsets.UTF_8);
+            if (user.length > 255 || pass.length > 255)
+                throw new ProtocolException("SOCKS5 credentials too long for upstream proxy " + this);
+            ByteArrayOutputStream auth = new ByteArrayOutputStream();
+            auth.write(0x01);
+            auth.write(user.length);
+            auth.write(user);
+            auth.write(pass.length);
+            auth.write(pass);
+            output.write(auth.toByteArray());
+            output.flush();
+            byte[] authReply = readFully(input, 2);
+            if (authReply[1] != 0)
+                throw new ProtocolException("SOCKS5 authentication failed for upstream proxy " + this);
+        }
+        else if (method != SOCKS5_NO_AUTH)
+        {
+            throw new ProtocolException("No acceptable SOCKS5 authentication method for upstream proxy " + this);
+        }
+
+        byte[] hostBytes = targetHost.getBytes(StandardCharsets.US_ASCII);
+        if (hostBytes.length > 255)
+            throw new ProtocolException("SOCKS5 target host too long " + targetHost);
+        ByteArrayOutputStream connect = new ByteArrayOutputStream();
+        connect.write(SOCKS5_VERSION);
+        connect.write(SOCKS5_COMMAND_CONNECT);
+        connect.write(0x00);
+        // Let the upstream proxy resolve the target host.
+        connect.write(SOCKS5_ADDRESS_DOMAIN);
+        connect.write(hostBytes.length);
+        connect.write(hostBytes);
+        connect.write((targetPort >> 8) & 0xFF);
+        connect.write(targetPort & 0xFF);
+        output.write(connect.toByteArray());
+        output.flush();
+
+        byte[] reply = readFully(input, 4);
+        if (reply[0] != SOCKS5_VERSION)
+            throw new ProtocolException("Invalid SOCKS5 version from upstream proxy " + this);
+        if (reply[1] != 0)
+            throw new ProtocolException(String.format("Upstream proxy %s refused SOCKS5 CONNECT %s:%d: reply %d", this, targetHost, targetPort, reply[1] & 0xFF));
+        int addressLength;
+        switch (reply[3])
+        {
+            case SOCKS5_ADDRESS_IPV4:
+                addressLength = 4;
+                break;
+            case SOCKS5_ADDRESS_IPV6:
+                addressLength = 16;
+                break;
+            case SOCKS5_ADDRESS_DOMAIN:
+                addressLength = readFully(input, 1)[0] & 0xFF;
+                break;
+            default:
+                throw new ProtocolException("Invalid SOCKS5 address type from upstream proxy " + this);
+        }
+        // Skip the bound address and port.
+        readFully(input, addressLength + 2);
+    }
+
+    private byte[] readFully(InputStream input, int length) throws IOException
+    {
+        byte[] bytes = input.readNBytes(length);
+        if (bytes.length < length)
+            throw new EOFException("Unexpected EOF from upstream proxy " + this);
+        return bytes;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s://%s:%d]", getClass().getSimpleName(), hashCode(), type, host, port);
+    }
+}
@@ -17,6 +17,8 @@ import java.io.BufferedReader;
 import java.io.ByteArrayInputStream;
 import java.io.IOException;
 import java.io.InputStreamReader;
+import java.util.HashMap;
+import java.util.Map;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
 import javax.servlet.ServletException;
@@ -37,9 +39,12 @@ import org.junit.jupiter.api.AfterEach;
 import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
 
+import static org.awaitility.Awaitility.await;
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
 import static org.junit.jupiter.api.Assertions.assertEquals;
 
 public class BalancerServletTest
@@ -47,7 +52,9 @@ public class BalancerServletTest
     private static final String CONTEXT_PATH = "/context";
     private static final String SERVLET_PATH = "/mapping";
 
+    private final Map<String, String> initParameters = new HashMap<>();
     private boolean stickySessions;
+    private ServletHolder balancerServletHolder;
     private Server server1;
     private Server server2;
     private Server balancer;
@@ -77,7 +84,8 @@ public class BalancerServletTest
         server2 = createServer(new ServletHolder(servletClass), "node2");
         server2.start();
 
-        ServletHolder balancerServletHolder = new ServletHolder(BalancerServlet.class);
+        balancerServletHolder = new ServletHolder(BalancerServlet.class);
+        balancerServletHolder.setInitParameters(initParameters);
         balancerServletHolder.setInitParameter("stickySessions", String.valueOf(stickySessions));
         balancerServletHolder.setInitParameter("proxyPassReverse", "true");
         balancerServletHolder.setInitParameter("balancerMember." + "node1" + ".proxyTo", "http://localhost:" + getServerPort(server1));
@@ -183,6 +191,133 @@ public class BalancerServletTest
         assertThat(response.getContentAsString(), containsString("pathInfo='/test/\n'"));
     }
 
+    @Test
+    public void testLeastConnectionsBalancer() throws Exception
+    {
+        initParameters.put("loadBalancingPolicy", "leastConnections");
+        startBalancer(CounterServlet.class);
+        for (int i = 0; i < 10; i++)
+        {
+            byte[] responseBytes = sendRequestToBalancer("/leastConnections");
+            String returnedCounter = readFirstLine(responseBytes);
+            // Without concurrent requests, backends are selected in turn.
+            String expectedCounter = String.valueOf(i / 2);
+            assertEquals(expectedCounter, returnedCounter);
+        }
+    }
+
+    @Test
+    public void testEWMABalancer() throws Exception
+    {
+        initParameters.put("loadBalancingPolicy", "ewma");
+        startBalancer(CounterServlet.class);
+        for (int i = 0; i < 10; i++)
+        {
+            assertThat(getBalancedResponse("/ewma").getStatus(), is(200));
+        }
+        for (Backend backend : getBalancerServlet().getBackends())
+        {
+            assertThat(backend.getActiveRequests(), is(0));
+        }
+    }
+
+    @Test
+    public void testStickyCookieBalancer() throws Exception
+    {
+        initParameters.put("stickyCookie", "BALANCER");
+        startBalancer(CounterServlet.class);
+        ContentResponse response = getBalancedResponse("/stickyCookie");
+        assertThat(response.getHeaders().getValuesList("Set-Cookie").toString(), containsString("BALANCER=node"));
+        for (int i = 1; i < 10; i++)
+        {
+            response = getBalancedResponse("/stickyCookie");
+            // The cookie is only set when the backend changes.
+            assertThat(response.getHeaders().getValuesList("Set-Cookie").toString(), not(containsString("BALANCER")));
+            assertEquals(String.valueOf(i), readFirstLine(response.getContent()));
+        }
+    }
+
+    @Test
+    public void testStickyHashBalancer() throws Exception
+    {
+        initParameters.put("stickyHash", "X-User");
+        startBalancer(CounterServlet.class);
+        for (int i = 0; i < 10; i++)
+        {
+            ContentResponse response = client.newRequest("localhost", getServerPort(balancer))
+                .path(CONTEXT_PATH + SERVLET_PATH + "/stickyHash")
+                .headers(headers -> headers.put("X-User", "user1"))
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            // Counter should increment every request
+            assertEquals(String.valueOf(i), readFirstLine(response.getContent()));
+        }
+    }
+
+    @Test
+    public void testOutlierEjection() throws Exception
+    {
+        initParameters.put("outlierConsecutiveFailures", "1");
+        startBalancer(CounterServlet.class);
+        server1.stop();
+
+        int failures = 0;
+        for (int i = 0; i < 10; i++)
+        {
+            if (getBalancedResponse("/outlier").getStatus() != 200)
+                ++failures;
+        }
+        // Only the first request to the stopped backend fails.
+        assertEquals(1, failures);
+        Backend backend = findBackend("node1");
+        assertThat(backend.isEjected(), is(true));
+        assertThat(backend.getFailures(), is(1L));
+        // The other backend is not ejected, as at most half of the backends can be ejected.
+        assertThat(findBackend("node2").isAvailable(), is(true));
+    }
+
+    @Test
+    public void testHealthChecks() throws Exception
+    {
+        initParameters.put("outlierConsecutiveFailures", "0");
+        initParameters.put("healthCheckPath", CONTEXT_PATH + SERVLET_PATH + "/health");
+        initParameters.put("healthCheckInterval", "100");
+        initParameters.put("unhealthyThreshold", "1");
+        startBalancer(CounterServlet.class);
+        Backend backend = findBackend("node1");
+        assertThat(backend.isHealthy(), is(true));
+
+        server1.stop();
+        await().atMost(5, TimeUnit.SECONDS).until(backend::isHealthy, is(false));
+        for (int i = 0; i < 10; i++)
+        {
+            assertThat(getBalancedResponse("/health").getStatus(), is(200));
+        }
+
+        server2.stop();
+        Backend backend2 = findBackend("node2");
+        await().atMost(5, TimeUnit.SECONDS).until(backend2::isHealthy, is(false));
+        // No backend available.
+        assertThat(getBalancedResponse("/health").getStatus(), is(503));
+    }
+
+    private BalancerServlet getBalancerServlet() throws ServletException
+    {
+        return (BalancerServlet)balancerServletHolder.getServlet();
+    }
+
+    private Backend findBackend(String name) throws ServletException
+    {
+        Backend result = null;
+        for (Backend backend : getBalancerServlet().getBackends())
+        {
+            if (backend.getName().equals(name))
+                result = backend;
+        }
+        assertThat(result, notNullValue());
+        return result;
+    }
+
     private String readFirstLine(byte[] responseBytes) throws IOException
     {
         BufferedReader reader = new BufferedReader(new InputStreamReader(new ByteArrayInputStream(responseBytes)));
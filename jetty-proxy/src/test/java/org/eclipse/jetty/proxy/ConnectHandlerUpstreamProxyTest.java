@@ -0,0 +1,254 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.net.ServerSocket;
+import java.net.Socket;
+import java.nio.charset.StandardCharsets;
+import java.util.Base64;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class ConnectHandlerUpstreamProxyTest extends AbstractConnectHandlerTest
+{
+    private final AtomicInteger upstreamConnects = new AtomicInteger();
+    private Server upstream;
+    private ServerConnector upstreamConnector;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        serverConnector = new ServerConnector(server, 1, 1);
+        server.addConnector(serverConnector);
+        server.setHandler(new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getOutputStream().print(request.getMethod() + " " + target);
+            }
+        });
+        server.start();
+
+        upstream = new Server();
+        upstreamConnector = new ServerConnector(upstream, 1, 1);
+        upstream.addConnector(upstreamConnector);
+        upstream.setHandler(new ConnectHandler()
+        {
+            @Override
+            protected boolean handleAuthentication(HttpServletRequest request, HttpServletResponse response, String address)
+            {
+                upstreamConnects.incrementAndGet();
+                String credentials = Base64.getEncoder().encodeToString("user:secret".getBytes(StandardCharsets.UTF_8));
+                return ("Basic " + credentials).equals(request.getHeader(HttpHeader.PROXY_AUTHORIZATION.asString()));
+            }
+        });
+        upstream.start();
+
+        prepareProxy();
+    }
+
+    @AfterEach
+    public void disposeUpstream() throws Exception
+    {
+        upstream.stop();
+    }
+
+    private UpstreamProxy newHTTPUpstreamProxy(String password)
+    {
+        return new UpstreamProxy(UpstreamProxy.Type.HTTP, "localhost", upstreamConnector.getLocalPort(), "user", password);
+    }
+
+    private HttpTester.Response connect(Socket socket, int port) throws IOException
+    {
+        String hostPort = "localhost:" + port;
+        String request =
+            "CONNECT " + hostPort + " HTTP/1.1\r\n" +
+                "Host: " + hostPort + "\r\n" +
+                "\r\n";
+        OutputStream output = socket.getOutputStream();
+        output.write(request.getBytes(StandardCharsets.UTF_8));
+        output.flush();
+        return HttpTester.parseResponse(HttpTester.from(socket.getInputStream()));
+    }
+
+    private HttpTester.Response get(Socket socket, String path) throws IOException
+    {
+        String request =
+            "GET " + path + " HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "\r\n";
+        OutputStream output = socket.getOutputStream();
+        output.write(request.getBytes(StandardCharsets.UTF_8));
+        output.flush();
+        return HttpTester.parseResponse(HttpTester.from(socket.getInputStream()));
+    }
+
+    @Test
+    public void testTunnelThroughHTTPUpstreamProxy() throws Exception
+    {
+        connectHandler.setUpstreamProxy(newHTTPUpstreamProxy("secret"));
+
+        try (Socket socket = newSocket())
+        {
+            assertEquals(HttpStatus.OK_200, connect(socket, serverConnector.getLocalPort()).getStatus());
+            HttpTester.Response response = get(socket, "/echo");
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("GET /echo", response.getContent());
+        }
+        assertEquals(1, upstreamConnects.get());
+    }
+
+    @Test
+    public void testHTTPUpstreamProxyAuthenticationFailure() throws Exception
+    {
+        connectHandler.setUpstreamProxy(newHTTPUpstreamProxy("wrong"));
+
+        try (Socket socket = newSocket())
+        {
+            assertEquals(HttpStatus.INTERNAL_SERVER_ERROR_500, connect(socket, serverConnector.getLocalPort()).getStatus());
+        }
+        assertEquals(1, upstreamConnects.get());
+    }
+
+    @Test
+    public void testTunnelThroughSOCKS5UpstreamProxy() throws Exception
+    {
+        try (ServerSocket socks = new ServerSocket(0))
+        {
+            CompletableFuture<String> targetRequest = new CompletableFuture<>();
+            Thread thread = new Thread(() ->
+            {
+                try (Socket socket = socks.accept())
+                {
+                    InputStream input = socket.getInputStream();
+                    OutputStream output = socket.getOutputStream();
+                    // Greeting: version, 2 methods, no auth and username/password.
+                    assertArrayEquals(new byte[]{5, 2, 0, 2}, input.readNBytes(4));
+                    output.write(new byte[]{5, 2});
+                    // Username/password authentication.
+                    assertEquals(1, input.read());
+                    String user = new String(input.readNBytes(input.read()), StandardCharsets.UTF_8);
+                    String pass = new String(input.readNBytes(input.read()), StandardCharsets.UTF_8);
+                    output.write(new byte[]{1, (byte)("user".equals(user) && "secret".equals(pass) ? 0 : 1)});
+                    // Connect command to a domain address.
+                    assertArrayEquals(new byte[]{5, 1, 0, 3}, input.readNBytes(4));
+                    String host = new String(input.readNBytes(input.read()), StandardCharsets.US_ASCII);
+                    byte[] portBytes = input.readNBytes(2);
+                    int port = ((portBytes[0] & 0xFF) << 8) | (portBytes[1] & 0xFF);
+                    // Reply with an IPv4 bound address.
+                    output.write(new byte[]{5, 0, 0, 1, 127, 0, 0, 1, 0, 0});
+                    output.flush();
+
+                    // Act as the target server.
+                    HttpTester.Request request = HttpTester.parseRequest(HttpTester.from(input));
+                    targetRequest.complete(host + ":" + port + " " + request.getMethod() + " " + request.getURI());
+                    output.write("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nsocks".getBytes(StandardCharsets.UTF_8));
+                    output.flush();
+                    input.read();
+                }
+                catch (Throwable x)
+                {
+                    targetRequest.completeExceptionally(x);
+                }
+            });
+            thread.start();
+
+            connectHandler.setUpstreamProxy(new UpstreamProxy(UpstreamProxy.Type.SOCKS5, "localhost", socks.getLocalPort(), "user", "secret"));
+            try (Socket socket = newSocket())
+            {
+                assertEquals(HttpStatus.OK_200, connect(socket, 8443).getStatus());
+                HttpTester.Response response = get(socket, "/socks");
+                assertEquals(HttpStatus.OK_200, response.getStatus());
+                assertEquals("socks", response.getContent());
+            }
+            assertEquals("localhost:8443 GET /socks", targetRequest.get(5, TimeUnit.SECONDS));
+            thread.join(5000);
+        }
+    }
+
+    @Test
+    public void testTunnelPolicy() throws Exception
+    {
+        UpstreamProxy upstreamProxy = newHTTPUpstreamProxy("secret");
+        int serverPort = serverConnector.getLocalPort();
+        connectHandler.setTunnelPolicy(target ->
+        {
+            if (target.getPort() != serverPort)
+                return TunnelPolicy.Decision.deny();
+            switch (target.getRequest().getHeader("X-Route"))
+            {
+                case "direct":
+                    return TunnelPolicy.Decision.direct();
+                case "chain":
+                    return TunnelPolicy.Decision.chain(upstreamProxy);
+                default:
+                    return TunnelPolicy.Decision.deny(HttpStatus.PROXY_AUTHENTICATION_REQUIRED_407);
+            }
+        });
+
+        for (String route : new String[]{"direct", "chain"})
+        {
+            String hostPort = "localhost:" + serverPort;
+            String request =
+                "CONNECT " + hostPort + " HTTP/1.1\r\n" +
+                    "Host: " + hostPort + "\r\n" +
+                    "X-Route: " + route + "\r\n" +
+                    "\r\n";
+            try (Socket socket = newSocket())
+            {
+                socket.getOutputStream().write(request.getBytes(StandardCharsets.UTF_8));
+                assertEquals(HttpStatus.OK_200, HttpTester.parseResponse(HttpTester.from(socket.getInputStream())).getStatus());
+                assertEquals("GET /" + route, get(socket, "/" + route).getContent());
+            }
+            assertEquals("chain".equals(route) ? 1 : 0, upstreamConnects.get());
+        }
+
+        try (Socket socket = newSocket())
+        {
+            String request =
+                "CONNECT localhost:" + serverPort + " HTTP/1.1\r\n" +
+                    "Host: localhost:" + serverPort + "\r\n" +
+                    "X-Route: other\r\n" +
+                    "\r\n";
+            socket.getOutputStream().write(request.getBytes(StandardCharsets.UTF_8));
+            assertEquals(HttpStatus.PROXY_AUTHENTICATION_REQUIRED_407, HttpTester.parseResponse(HttpTester.from(socket.getInputStream())).getStatus());
+        }
+
+        try (Socket socket = newSocket())
+        {
+            assertEquals(HttpStatus.FORBIDDEN_403, connect(socket, serverPort + 1).getStatus());
+        }
+    }
+}
@@ -0,0 +1,193 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.util.StringRequestContent;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+public class MirrorHandlerTest
+{
+    private final BlockingQueue<String> mirrored = new LinkedBlockingQueue<>();
+    private Server shadow;
+    private ServerConnector shadowConnector;
+    private Server server;
+    private ServerConnector connector;
+    private MirrorHandler mirrorHandler;
+    private HttpClient client;
+
+    private void start(AbstractHandler shadowHandler) throws Exception
+    {
+        shadow = new Server();
+        shadowConnector = new ServerConnector(shadow);
+        shadow.addConnector(shadowConnector);
+        shadow.setHandler(shadowHandler);
+        shadow.start();
+
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+        mirrorHandler = new MirrorHandler();
+        mirrorHandler.setMirrorURI("http://localhost:" + shadowConnector.getLocalPort());
+        mirrorHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                String content = IO.toString(request.getInputStream(), StandardCharsets.UTF_8);
+                response.getWriter().print("primary:" + content);
+            }
+        });
+        server.setHandler(mirrorHandler);
+
+        client = new HttpClient();
+        server.addBean(client);
+        server.start();
+    }
+
+    private void start() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                String content = IO.toString(request.getInputStream(), StandardCharsets.UTF_8);
+                mirrored.offer(request.getMethod() + " " + request.getRequestURI() + "?" + request.getQueryString() +
+                    " " + request.getHeader("X-Test") + " " + request.getHeader("X-Mirrored-From") + " " + content);
+            }
+        });
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (server != null)
+            server.stop();
+        if (shadow != null)
+            shadow.stop();
+    }
+
+    @Test
+    public void testRequestIsMirrored() throws Exception
+    {
+        start();
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .method(HttpMethod.POST)
+            .path("/path?a=b")
+            .headers(headers -> headers.put("X-Test", "value"))
+            .body(new StringRequestContent("hello"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), is("primary:hello"));
+        String request = mirrored.poll(5, TimeUnit.SECONDS);
+        assertThat(request, is("POST /path?a=b value localhost:" + connector.getLocalPort() + " hello"));
+        await().atMost(5, TimeUnit.SECONDS).until(mirrorHandler::getPending, is(0));
+        assertThat(mirrorHandler.getMirrored(), is(1L));
+        assertThat(mirrorHandler.getFailed(), is(0L));
+    }
+
+    @Test
+    public void testExcludedPathAndZeroPercentageNotMirrored() throws Exception
+    {
+        start();
+        mirrorHandler.excludePath("/private/*");
+
+        ContentResponse response = client.GET("http://localhost:" + connector.getLocalPort() + "/private/data");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        mirrorHandler.setPercentage(0);
+        response = client.GET("http://localhost:" + connector.getLocalPort() + "/public");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        assertThat(mirrored.poll(1, TimeUnit.SECONDS), nullValue());
+        assertThat(mirrorHandler.getMirrored(), is(0L));
+    }
+
+    @Test
+    public void testContentTooLargeNotMirrored() throws Exception
+    {
+        start();
+        mirrorHandler.setMaxContentSize(4);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .method(HttpMethod.POST)
+            .body(new StringRequestContent("too large"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertThat(response.getContentAsString(), is("primary:too large"));
+        assertThat(mirrored.poll(1, TimeUnit.SECONDS), nullValue());
+        assertThat(mirrorHandler.getDropped(), is(1L));
+    }
+
+    @Test
+    public void testShadowFailureDoesNotAffectPrimary() throws Exception
+    {
+        CountDownLatch latch = new CountDownLatch(1);
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                try
+                {
+                    // Slower than the mirror timeout.
+                    latch.await(5, TimeUnit.SECONDS);
+                }
+                catch (InterruptedException x)
+                {
+                    throw new RuntimeException(x);
+                }
+            }
+        });
+        mirrorHandler.setTimeout(500);
+
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .timeout(2, TimeUnit.SECONDS)
+            .send();
+
+        assertThat(response.getContentAsString(), is("primary:"));
+        await().atMost(5, TimeUnit.SECONDS).until(mirrorHandler::getFailed, is(1L));
+        latch.countDown();
+    }
+}
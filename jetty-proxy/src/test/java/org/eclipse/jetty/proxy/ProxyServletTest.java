@@ -72,6 +72,7 @@ import org.eclipse.jetty.client.util.AsyncRequestContent;
 import org.eclipse.jetty.client.util.BufferingResponseListener;
 import org.eclipse.jetty.client.util.BytesRequestContent;
 import org.eclipse.jetty.client.util.InputStreamResponseListener;
+import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpHeaderValue;
 import org.eclipse.jetty.http.HttpMethod;
@@ -102,11 +103,14 @@ import static org.eclipse.jetty.http.tools.matchers.HttpFieldsMatchers.containsH
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.equalTo;
+import static org.hamcrest.Matchers.greaterThan;
 import static org.hamcrest.Matchers.greaterThanOrEqualTo;
 import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
 import static org.junit.jupiter.api.Assertions.assertArrayEquals;
 import static org.junit.jupiter.api.Assertions.assertEquals;
 import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
 import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
@@ -1700,4 +1704,206 @@ public class ProxyServletTest
             assertEquals(HttpStatus.OK_200, response.getStatus());
         }
     }
+
+    @ParameterizedTest
+    @MethodSource("impls")
+    public void testProxyTrailers(Class<? extends ProxyServlet> proxyServletClass) throws Exception
+    {
+        startServer(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                // The request trailers are available after the request content has been read.
+                response.setTrailerFields(() -> Map.of("X-Echo", String.valueOf(request.getTrailerFields().get("x-request"))));
+                IO.copy(request.getInputStream(), response.getOutputStream());
+            }
+        });
+        startProxy(proxyServletClass);
+        startClient();
+
+        byte[] content = "hello".getBytes(StandardCharsets.UTF_8);
+        AsyncRequestContent requestContent = new AsyncRequestContent(ByteBuffer.wrap(content));
+        requestContent.close();
+        ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+            .headers(headers -> headers.put(HttpHeader.TE, "trailers"))
+            .body(requestContent)
+            .trailers(() -> HttpFields.build().put("X-Request", "request"))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertArrayEquals(content, response.getContent());
+        HttpFields trailers = response.getTrailers();
+        assertNotNull(trailers);
+        assertEquals("request", trailers.get("X-Echo"));
+    }
+
+    @ParameterizedTest
+    @ValueSource(classes = {ProxyServlet.class, AsyncProxyServlet.class})
+    public void testGrpcResponseContentNotBuffered(Class<? extends ProxyServlet> proxyServletClass) throws Exception
+    {
+        CountDownLatch contentLatch = new CountDownLatch(1);
+        startServer(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                IO.copy(request.getInputStream(), OutputStream.nullOutputStream());
+                response.setContentType("application/grpc");
+                response.setTrailerFields(() -> Map.of("grpc-status", "0"));
+                ServletOutputStream output = response.getOutputStream();
+                output.write(new byte[]{0, 0, 0, 0, 1, 1});
+                output.flush();
+                try
+                {
+                    // Send the second message only after the client received the first.
+                    if (!contentLatch.await(5, TimeUnit.SECONDS))
+                        throw new IOException("first message not received");
+                }
+                catch (InterruptedException x)
+                {
+                    throw new InterruptedIOException();
+                }
+                output.write(new byte[]{0, 0, 0, 0, 1, 2});
+            }
+        });
+        startProxy(proxyServletClass);
+        startClient();
+
+        ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+            .method(HttpMethod.POST)
+            .headers(headers -> headers.put(HttpHeader.TE, "trailers"))
+            .body(new BytesRequestContent("application/grpc", new byte[]{0, 0, 0, 0, 0}))
+            .onResponseContent((r, buffer) -> contentLatch.countDown())
+            .timeout(10, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(12, response.getContent().length);
+        assertNotNull(response.getTrailers());
+        assertEquals("0", response.getTrailers().get("grpc-status"));
+    }
+
+    @ParameterizedTest
+    @MethodSource("impls")
+    public void testGrpcTimeout(Class<? extends ProxyServlet> proxyServletClass) throws Exception
+    {
+        CountDownLatch serverLatch = new CountDownLatch(1);
+        startServer(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                IO.copy(request.getInputStream(), OutputStream.nullOutputStream());
+                try
+                {
+                    serverLatch.await(5, TimeUnit.SECONDS);
+                }
+                catch (InterruptedException x)
+                {
+                    throw new InterruptedIOException();
+                }
+            }
+        });
+        startProxy(proxyServletClass);
+        startClient();
+
+        ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+            .method(HttpMethod.POST)
+            .headers(headers -> headers.put(HttpHeader.TE, "trailers").put("grpc-timeout", "500m"))
+            .body(new BytesRequestContent("application/grpc", new byte[]{0, 0, 0, 0, 0}))
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        serverLatch.countDown();
+
+        // The proxy failure is reported as a trailers-only response.
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals("4", response.getHeaders().get("grpc-status"));
+        assertEquals(0, response.getContent().length);
+    }
+
+    @ParameterizedTest
+    @ValueSource(classes = {ProxyServlet.class, AsyncProxyServlet.class})
+    public void testSpooledResponseReleasesServer(Class<? extends ProxyServlet> proxyServletClass) throws Exception
+    {
+        byte[] content = new byte[16 * 1024 * 1024];
+        new Random().nextBytes(content);
+        CountDownLatch serverLatch = new CountDownLatch(1);
+        startServer(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                ServletOutputStream output = response.getOutputStream();
+                for (int offset = 0; offset < content.length; offset += 64 * 1024)
+                {
+                    output.write(content, offset, 64 * 1024);
+                }
+                output.close();
+                serverLatch.countDown();
+            }
+        });
+        Map<String, String> params = new HashMap<>();
+        params.put("spooling", "true");
+        params.put("spoolMemoryThreshold", String.valueOf(128 * 1024));
+        params.put("spoolMaxResponseSize", "-1");
+        startProxy(proxyServletClass, params);
+        startClient();
+
+        InputStreamResponseListener listener = new InputStreamResponseListener();
+        client.newRequest("localhost", serverConnector.getLocalPort())
+            .timeout(30, TimeUnit.SECONDS)
+            .send(listener);
+        Response response = listener.get(5, TimeUnit.SECONDS);
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+
+        // The server completes although the client has not read the content.
+        assertTrue(serverLatch.await(10, TimeUnit.SECONDS));
+        ResponseSpooler spooler = ((ProxyServlet)proxyServlet).getResponseSpooler();
+        assertThat(spooler.getDiskSizeMax(), greaterThanOrEqualTo((long)content.length / 2));
+
+        assertArrayEquals(content, IO.readBytes(listener.getInputStream()));
+        assertEquals(1, spooler.getSpoolsTotal());
+        assertEquals(content.length, spooler.getSpooledBytes());
+        assertEquals(0, spooler.getOverflows());
+        // The spool is released before the response completes.
+        assertEquals(0, spooler.getSpools());
+        assertEquals(0, spooler.getMemorySize());
+        assertEquals(0, spooler.getDiskSize());
+    }
+
+    @ParameterizedTest
+    @ValueSource(classes = {ProxyServlet.class, AsyncProxyServlet.class})
+    public void testSpoolOverflow(Class<? extends ProxyServlet> proxyServletClass) throws Exception
+    {
+        byte[] content = new byte[256 * 1024];
+        new Random().nextBytes(content);
+        startServer(new HttpServlet()
+        {
+            @Override
+            protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.getOutputStream().write(content);
+            }
+        });
+        Map<String, String> params = new HashMap<>();
+        params.put("spooling", "true");
+        params.put("spoolMaxResponseSize", String.valueOf(16 * 1024));
+        startProxy(proxyServletClass, params);
+        startClient();
+
+        ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        // Content beyond the response limit is proxied without being spooled.
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertArrayEquals(content, response.getContent());
+        ResponseSpooler spooler = ((ProxyServlet)proxyServlet).getResponseSpooler();
+        assertThat(spooler.getSpooledBytes(), lessThanOrEqualTo(16L * 1024));
+        assertThat(spooler.getOverflows(), greaterThan(0L));
+        assertEquals(0, spooler.getSpools());
+        assertEquals(0, spooler.getMemorySize());
+    }
 }
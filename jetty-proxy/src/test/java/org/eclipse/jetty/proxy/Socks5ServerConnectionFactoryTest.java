@@ -0,0 +1,240 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.proxy;
+
+import java.io.DataInputStream;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.net.Socket;
+import java.nio.charset.StandardCharsets;
+import java.security.Principal;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.Socks5;
+import org.eclipse.jetty.client.Socks5Proxy;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.DetectorConnectionFactory;
+import org.eclipse.jetty.server.HttpConnectionFactory;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class Socks5ServerConnectionFactoryTest
+{
+    private Server server;
+    private ServerConnector serverConnector;
+    private Server proxy;
+    private ServerConnector proxyConnector;
+    private Socks5ServerConnectionFactory socks5;
+    private HttpClient client;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        serverConnector = new ServerConnector(server);
+        server.addConnector(serverConnector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setHeader("X-Target", target);
+            }
+        });
+        server.start();
+
+        proxy = new Server();
+        socks5 = new Socks5ServerConnectionFactory();
+    }
+
+    private void startProxy() throws Exception
+    {
+        proxyConnector = new ServerConnector(proxy, new DetectorConnectionFactory(socks5), new HttpConnectionFactory());
+        proxy.addConnector(proxyConnector);
+        proxy.start();
+    }
+
+    private void startClient(Socks5Proxy socks5Proxy) throws Exception
+    {
+        client = new HttpClient();
+        client.getProxyConfiguration().getProxies().add(socks5Proxy);
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose()
+    {
+        LifeCycle.stop(client);
+        LifeCycle.stop(proxy);
+        LifeCycle.stop(server);
+    }
+
+    @Test
+    public void testConnectWithoutAuthentication() throws Exception
+    {
+        startProxy();
+        startClient(new Socks5Proxy("localhost", proxyConnector.getLocalPort()));
+
+        for (int i = 0; i < 2; ++i)
+        {
+            ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+                .path("/path" + i)
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("/path" + i, response.getHeaders().get("X-Target"));
+        }
+    }
+
+    @Test
+    public void testConnectWithUsernamePassword() throws Exception
+    {
+        socks5.addAuthenticator(new Socks5ServerAuthenticator.UsernamePassword((username, password) ->
+            "user".equals(username) && "secret".equals(password) ? (Principal)() -> username : null));
+        startProxy();
+        Socks5Proxy socks5Proxy = new Socks5Proxy("localhost", proxyConnector.getLocalPort());
+        socks5Proxy.putAuthenticationFactory(new Socks5.UsernamePasswordAuthenticationFactory("user", "secret"));
+        startClient(socks5Proxy);
+
+        ContentResponse response = client.newRequest("localhost", serverConnector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+    }
+
+    @Test
+    public void testConnectWithWrongPassword() throws Exception
+    {
+        socks5.addAuthenticator(new Socks5ServerAuthenticator.UsernamePassword((username, password) -> null));
+        startProxy();
+        Socks5Proxy socks5Proxy = new Socks5Proxy("localhost", proxyConnector.getLocalPort());
+        socks5Proxy.putAuthenticationFactory(new Socks5.UsernamePasswordAuthenticationFactory("user", "wrong"));
+        startClient(socks5Proxy);
+
+        assertThrows(Exception.class, () -> client.newRequest("localhost", serverConnector.getLocalPort())
+            .timeout(5, TimeUnit.SECONDS)
+            .send());
+    }
+
+    @Test
+    public void testNoAcceptableMethod() throws Exception
+    {
+        socks5.addAuthenticator(new Socks5ServerAuthenticator.UsernamePassword((username, password) -> null));
+        startProxy();
+
+        try (Socket socket = new Socket("localhost", proxyConnector.getLocalPort()))
+        {
+            socket.setSoTimeout(5000);
+            OutputStream output = socket.getOutputStream();
+            // Offer only the no authentication method.
+            output.write(new byte[]{Socks5.VERSION, 1, Socks5.NoAuthenticationFactory.METHOD});
+            output.flush();
+
+            InputStream input = socket.getInputStream();
+            assertEquals(Socks5.VERSION, input.read());
+            assertEquals(0xFF, input.read());
+            assertEquals(-1, input.read());
+        }
+    }
+
+    @Test
+    public void testDestinationNotAllowed() throws Exception
+    {
+        socks5.getBlackListHosts().add("localhost:" + serverConnector.getLocalPort());
+        startProxy();
+
+        assertThat(request(Socks5.COMMAND_CONNECT, "localhost", serverConnector.getLocalPort()), is(0x02));
+    }
+
+    @Test
+    public void testUnsupportedCommand() throws Exception
+    {
+        startProxy();
+
+        // The BIND command.
+        assertThat(request((byte)0x02, "localhost", serverConnector.getLocalPort()), is(0x07));
+    }
+
+    @Test
+    public void testConnectionRefused() throws Exception
+    {
+        int port = serverConnector.getLocalPort();
+        server.stop();
+        startProxy();
+
+        assertThat(request(Socks5.COMMAND_CONNECT, "localhost", port), is(0x05));
+    }
+
+    @Test
+    public void testHttpOnSamePort() throws Exception
+    {
+        startProxy();
+
+        try (Socket socket = new Socket("localhost", proxyConnector.getLocalPort()))
+        {
+            socket.setSoTimeout(5000);
+            OutputStream output = socket.getOutputStream();
+            output.write("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+
+            String response = new String(socket.getInputStream().readAllBytes(), StandardCharsets.UTF_8);
+            assertThat(response.startsWith("HTTP/1.1 404 "), is(true));
+        }
+    }
+
+    /**
+     * @return the reply code of the SOCKS5 request, sent without authentication
+     */
+    private int request(byte command, String host, int port) throws Exception
+    {
+        try (Socket socket = new Socket("localhost", proxyConnector.getLocalPort()))
+        {
+            socket.setSoTimeout(5000);
+            OutputStream output = socket.getOutputStream();
+            output.write(new byte[]{Socks5.VERSION, 1, Socks5.NoAuthenticationFactory.METHOD});
+            byte[] hostBytes = host.getBytes(StandardCharsets.US_ASCII);
+            output.write(new byte[]{Socks5.VERSION, command, Socks5.RESERVED, Socks5.ADDRESS_TYPE_DOMAIN, (byte)hostBytes.length});
+            output.write(hostBytes);
+            output.write(new byte[]{(byte)(port >> 8), (byte)port});
+            output.flush();
+
+            DataInputStream input = new DataInputStream(socket.getInputStream());
+            assertEquals(Socks5.VERSION, input.read());
+            assertEquals(Socks5.NoAuthenticationFactory.METHOD, input.read());
+            assertEquals(Socks5.VERSION, input.read());
+            int reply = input.read();
+            assertEquals(Socks5.RESERVED, input.read());
+            assertEquals(Socks5.ADDRESS_TYPE_IPV4, input.read());
+            input.readFully(new byte[4 + 2]);
+            assertEquals(-1, input.read());
+            return reply;
+        }
+    }
+}
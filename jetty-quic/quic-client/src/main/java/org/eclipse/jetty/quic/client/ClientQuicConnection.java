@@ -18,6 +18,7 @@ import java.net.InetSocketAddress;
 import java.net.SocketAddress;
 import java.net.SocketTimeoutException;
 import java.nio.ByteBuffer;
+import java.nio.file.Path;
 import java.util.Collection;
 import java.util.List;
 import java.util.Map;
@@ -81,12 +82,15 @@ public class ClientQuicConnection extends QuicConnection
             quicheConfig.setApplicationProtos(protocols.toArray(String[]::new));
             quicheConfig.setDisableActiveMigration(quicConfiguration.isDisableActiveMigration());
             quicheConfig.setVerifyPeer(!connector.getSslContextFactory().isTrustAll());
+            Path keyLogFile = connector.getSslContextFactory().getEnabledKeyLogFile();
+            if (keyLogFile != null)
+                quicheConfig.setKeyLogPath(keyLogFile.toString());
             Map<String, Object> implCtx = quicConfiguration.getImplementationConfiguration();
             quicheConfig.setTrustedCertsPemPath((String)implCtx.get(QuicClientConnectorConfigurator.TRUSTED_CERTIFICATES_PEM_PATH_KEY));
             quicheConfig.setPrivKeyPemPath((String)implCtx.get(QuicClientConnectorConfigurator.PRIVATE_KEY_PEM_PATH_KEY));
             quicheConfig.setCertChainPemPath((String)implCtx.get(QuicClientConnectorConfigurator.CERTIFICATE_CHAIN_PEM_PATH_KEY));
-            // Idle timeouts must not be managed by Quiche.
-            quicheConfig.setMaxIdleTimeout(0L);
+            // Idle timeouts are managed by Jetty, unless explicitly configured.
+            quicheConfig.setMaxIdleTimeout(quicConfiguration.getMaxIdleTimeout());
             quicheConfig.setInitialMaxData((long)quicConfiguration.getSessionRecvWindow());
             quicheConfig.setInitialMaxStreamDataBidiLocal((long)quicConfiguration.getBidirectionalStreamRecvWindow());
             quicheConfig.setInitialMaxStreamDataBidiRemote((long)quicConfiguration.getBidirectionalStreamRecvWindow());
@@ -94,6 +98,12 @@ public class ClientQuicConnection extends QuicConnection
             quicheConfig.setInitialMaxStreamsUni((long)quicConfiguration.getMaxUnidirectionalRemoteStreams());
             quicheConfig.setInitialMaxStreamsBidi((long)quicConfiguration.getMaxBidirectionalRemoteStreams());
             quicheConfig.setCongestionControl(QuicheConfig.CongestionControl.CUBIC);
+            if (quicConfiguration.getInitialCongestionWindowPackets() > 0)
+                quicheConfig.setInitialCongestionWindowPackets((long)quicConfiguration.getInitialCongestionWindowPackets());
+            if (quicConfiguration.isDatagramsEnabled())
+                quicheConfig.setEnableDgram(true);
+            if (quicConfiguration.isEarlyDataEnabled())
+                quicheConfig.setEnableEarlyData(true);
 
             InetSocketAddress remoteAddress = (InetSocketAddress)context.get(ClientConnector.REMOTE_SOCKET_ADDRESS_CONTEXT_KEY);
 
@@ -101,6 +111,7 @@ public class ClientQuicConnection extends QuicConnection
                 LOG.debug("connecting to {} with protocols {}", remoteAddress, protocols);
 
             QuicheConnection quicheConnection = QuicheConnection.connect(quicheConfig, getEndPoint().getLocalAddress(), remoteAddress);
+            resumeTlsSession(quicheConnection, remoteAddress);
             ClientQuicSession session = new ClientQuicSession(getExecutor(), getScheduler(), getByteBufferPool(), quicheConnection, this, remoteAddress, context);
             pendingSessions.put(remoteAddress, session);
             if (LOG.isDebugEnabled())
@@ -111,6 +122,20 @@ public class ClientQuicConnection extends QuicConnection
             // Send the packets generated by the connect.
             session.flush();
 
+            // After the first flight, Quiche knows whether the
+            // resumed TLS session allows to send early data.
+            if (quicConfiguration.isEarlyDataEnabled() && session.isConnectionInEarlyData())
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("sending early data on {}", session);
+                pendingSessions.remove(remoteAddress);
+                addSession(quicheConnection.getSourceConnectionId(), session);
+                Runnable task = session.processEarlyData();
+                if (task != null)
+                    getExecutor().execute(task);
+                session.flush();
+            }
+
             fillInterested();
         }
         catch (IOException x)
@@ -119,6 +144,54 @@ public class ClientQuicConnection extends QuicConnection
         }
     }
 
+    private void resumeTlsSession(QuicheConnection quicheConnection, InetSocketAddress remoteAddress)
+    {
+        SessionTicketStore store = getSessionTicketStore();
+        if (store == null)
+            return;
+        String key = toSessionTicketKey(remoteAddress);
+        byte[] tlsSession = store.load(key);
+        if (tlsSession == null)
+            return;
+        boolean resumed = quicheConnection.setSession(tlsSession);
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} TLS session for {}", resumed ? "resuming" : "could not resume", key);
+        if (!resumed)
+            store.remove(key);
+    }
+
+    private void storeTlsSession(QuicSession session)
+    {
+        SessionTicketStore store = getSessionTicketStore();
+        if (store == null)
+            return;
+        SocketAddress remoteAddress = (SocketAddress)context.get(ClientConnector.REMOTE_SOCKET_ADDRESS_CONTEXT_KEY);
+        if (!(remoteAddress instanceof InetSocketAddress))
+            return;
+        try
+        {
+            byte[] tlsSession = session.getTlsSession();
+            if (tlsSession != null)
+                store.store(toSessionTicketKey((InetSocketAddress)remoteAddress), tlsSession);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("could not store TLS session for {}", session, x);
+        }
+    }
+
+    private SessionTicketStore getSessionTicketStore()
+    {
+        QuicConfiguration quicConfiguration = (QuicConfiguration)context.get(QuicConfiguration.CONTEXT_KEY);
+        return (SessionTicketStore)quicConfiguration.getImplementationConfiguration().get(QuicClientConnectorConfigurator.SESSION_TICKET_STORE_KEY);
+    }
+
+    private static String toSessionTicketKey(InetSocketAddress address)
+    {
+        return address.getHostString() + ":" + address.getPort();
+    }
+
     @Override
     public void onFillable()
     {
@@ -176,6 +249,8 @@ public class ClientQuicConnection extends QuicConnection
     @Override
     public void outwardClose(QuicSession session, Throwable failure)
     {
+        // The session is about to be disposed, save the TLS session while still possible.
+        storeTlsSession(session);
         super.outwardClose(session, failure);
         SocketAddress remoteAddress = session.getRemoteAddress();
         outwardClose(remoteAddress, failure);
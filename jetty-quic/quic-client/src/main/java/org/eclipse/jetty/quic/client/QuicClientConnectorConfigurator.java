@@ -53,6 +53,7 @@ public class QuicClientConnectorConfigurator extends ClientConnector.Configurato
     static final String PRIVATE_KEY_PEM_PATH_KEY = QuicClientConnectorConfigurator.class.getName() + ".privateKeyPemPath";
     static final String CERTIFICATE_CHAIN_PEM_PATH_KEY = QuicClientConnectorConfigurator.class.getName() + ".certificateChainPemPath";
     static final String TRUSTED_CERTIFICATES_PEM_PATH_KEY = QuicClientConnectorConfigurator.class.getName() + ".trustedCertificatesPemPath";
+    static final String SESSION_TICKET_STORE_KEY = QuicClientConnectorConfigurator.class.getName() + ".sessionTicketStore";
 
     private final QuicConfiguration configuration = new QuicConfiguration();
     private final UnaryOperator<Connection> configurator;
@@ -79,6 +80,26 @@ public class QuicClientConnectorConfigurator extends ClientConnector.Configurato
         return configuration;
     }
 
+    public SessionTicketStore getSessionTicketStore()
+    {
+        return (SessionTicketStore)configuration.getImplementationConfiguration().get(SESSION_TICKET_STORE_KEY);
+    }
+
+    /**
+     * <p>Sets the store for TLS sessions received from servers, used to resume
+     * TLS sessions and, if enabled, to send 0-RTT early data.</p>
+     *
+     * @param sessionTicketStore the store for TLS sessions, or null to disable TLS session resumption
+     * @see QuicConfiguration#setEarlyDataEnabled(boolean)
+     */
+    public void setSessionTicketStore(SessionTicketStore sessionTicketStore)
+    {
+        if (sessionTicketStore == null)
+            configuration.getImplementationConfiguration().remove(SESSION_TICKET_STORE_KEY);
+        else
+            configuration.getImplementationConfiguration().put(SESSION_TICKET_STORE_KEY, sessionTicketStore);
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -0,0 +1,204 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.client;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A store for serialized TLS sessions received from QUIC servers.</p>
+ * <p>A TLS session received in a previous connection can be used to resume
+ * the TLS session in a new connection to the same server, possibly sending
+ * 0-RTT early data if {@link org.eclipse.jetty.quic.common.QuicConfiguration#setEarlyDataEnabled(boolean)
+ * early data is enabled}.</p>
+ * <p>Keys are in the form {@code host:port}; the serialized TLS sessions
+ * contain secret material, so implementations must store them securely.</p>
+ *
+ * @see QuicClientConnectorConfigurator#setSessionTicketStore(SessionTicketStore)
+ */
+public interface SessionTicketStore
+{
+    /**
+     * @param key the server key
+     * @return the serialized TLS session for the given server, or null if there is none
+     */
+    public byte[] load(String key);
+
+    /**
+     * @param key the server key
+     * @param session the serialized TLS session for the given server
+     */
+    public void store(String key, byte[] session);
+
+    /**
+     * <p>Removes the serialized TLS session for the given server,
+     * typically because it could not be used to resume the TLS session.</p>
+     *
+     * @param key the server key
+     */
+    public void remove(String key);
+
+    /**
+     * <p>A {@link SessionTicketStore} that keeps a bounded number
+     * of TLS sessions in memory, evicting the least recently used.</p>
+     */
+    public static class InMemory implements SessionTicketStore
+    {
+        private final AutoLock lock = new AutoLock();
+        private final Map<String, byte[]> sessions;
+
+        public InMemory()
+        {
+            this(1024);
+        }
+
+        public InMemory(int maxSessions)
+        {
+            this.sessions = new LinkedHashMap<>(16, 0.75F, true)
+            {
+                @Override
+                protected boolean removeEldestEntry(Map.Entry<String, byte[]> eldest)
+                {
+                    return size() > maxSessions;
+                }
+            };
+        }
+
+        @Override
+        public byte[] load(String key)
+        {
+            try (AutoLock l = lock.lock())
+            {
+                return sessions.get(key);
+            }
+        }
+
+        @Override
+        public void store(String key, byte[] session)
+        {
+            try (AutoLock l = lock.lock())
+            {
+                sessions.put(key, session);
+            }
+        }
+
+        @Override
+        public void remove(String key)
+        {
+            try (AutoLock l = lock.lock())
+            {
+                sessions.remove(key);
+            }
+        }
+
+        public int getSize()
+        {
+            try (AutoLock l = lock.lock())
+            {
+                return sessions.size();
+            }
+        }
+    }
+
+    /**
+     * <p>A {@link SessionTicketStore} that stores one file per server
+     * in a directory, so that TLS sessions survive client restarts.</p>
+     * <p>The directory should only be accessible by the user running the client.</p>
+     */
+    public static class FileSystem implements SessionTicketStore
+    {
+        private static final Logger LOG = LoggerFactory.getLogger(FileSystem.class);
+
+        private final Path directory;
+
+        public FileSystem(Path directory)
+        {
+            this.directory = Objects.requireNonNull(directory);
+        }
+
+        public Path getDirectory()
+        {
+            return directory;
+        }
+
+        @Override
+        public byte[] load(String key)
+        {
+            Path file = toFile(key);
+            try
+            {
+                if (Files.isRegularFile(file))
+                    return Files.readAllBytes(file);
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not load TLS session from {}", file, x);
+            }
+            return null;
+        }
+
+        @Override
+        public void store(String key, byte[] session)
+        {
+            Path file = toFile(key);
+            try
+            {
+                Files.createDirectories(directory);
+                // Write to a temporary file first, so that readers never see partial content.
+                Path temp = Files.createTempFile(directory, file.getFileName().toString(), ".tmp");
+                Files.write(temp, session);
+                Files.move(temp, file, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not store TLS session to {}", file, x);
+            }
+        }
+
+        @Override
+        public void remove(String key)
+        {
+            Path file = toFile(key);
+            try
+            {
+                Files.deleteIfExists(file);
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not remove TLS session {}", file, x);
+            }
+        }
+
+        private Path toFile(String key)
+        {
+            // Keys are host:port, possibly with IPv6 addresses, so encode them.
+            String name = StringUtil.toHexString(key.getBytes(StandardCharsets.UTF_8));
+            return directory.resolve(name + ".session");
+        }
+    }
+}
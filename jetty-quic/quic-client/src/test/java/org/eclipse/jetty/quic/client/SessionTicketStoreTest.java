@@ -0,0 +1,68 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.client;
+
+import java.nio.file.Path;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+
+@ExtendWith(WorkDirExtension.class)
+public class SessionTicketStoreTest
+{
+    public WorkDir workDir;
+
+    @Test
+    public void testInMemoryEvictsLeastRecentlyUsed()
+    {
+        SessionTicketStore.InMemory store = new SessionTicketStore.InMemory(2);
+        store.store("a:443", new byte[]{1});
+        store.store("b:443", new byte[]{2});
+        // Access "a" so that "b" becomes the least recently used.
+        assertArrayEquals(new byte[]{1}, store.load("a:443"));
+        store.store("c:443", new byte[]{3});
+
+        assertThat(store.getSize(), is(2));
+        assertThat(store.load("b:443"), nullValue());
+        assertArrayEquals(new byte[]{3}, store.load("c:443"));
+
+        store.remove("a:443");
+        assertThat(store.load("a:443"), nullValue());
+    }
+
+    @Test
+    public void testFileSystem()
+    {
+        Path directory = workDir.getEmptyPathDir().resolve("sessions");
+        SessionTicketStore.FileSystem store = new SessionTicketStore.FileSystem(directory);
+        assertThat(store.load("[::1]:443"), nullValue());
+
+        store.store("[::1]:443", new byte[]{1, 2, 3});
+        store.store("[::1]:443", new byte[]{4, 5});
+
+        // A new store on the same directory sees the stored session.
+        SessionTicketStore.FileSystem other = new SessionTicketStore.FileSystem(directory);
+        assertArrayEquals(new byte[]{4, 5}, other.load("[::1]:443"));
+
+        other.remove("[::1]:443");
+        assertThat(store.load("[::1]:443"), nullValue());
+    }
+}
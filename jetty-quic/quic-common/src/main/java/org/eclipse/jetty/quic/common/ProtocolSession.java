@@ -13,6 +13,8 @@
 
 package org.eclipse.jetty.quic.common;
 
+import java.io.IOException;
+import java.nio.ByteBuffer;
 import java.util.ArrayDeque;
 import java.util.List;
 import java.util.Map;
@@ -135,6 +137,39 @@ public abstract class ProtocolSession extends ContainerLifeCycle
 
     protected abstract boolean onReadable(long readableStreamId);
 
+    protected void processDatagrams()
+    {
+        while (true)
+        {
+            ByteBuffer datagram;
+            try
+            {
+                datagram = session.receiveDatagram();
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not receive datagram on {}", this, x);
+                return;
+            }
+            if (datagram == null)
+                return;
+            if (LOG.isDebugEnabled())
+                LOG.debug("received datagram of {} bytes on {}", datagram.remaining(), this);
+            onDatagram(datagram);
+        }
+    }
+
+    /**
+     * <p>Callback method invoked when a QUIC DATAGRAM frame is received.</p>
+     * <p>The default implementation discards the datagram.</p>
+     *
+     * @param datagram the datagram bytes
+     */
+    protected void onDatagram(ByteBuffer datagram)
+    {
+    }
+
     public void openProtocolEndPoint(QuicStreamEndPoint endPoint)
     {
         Connection connection = getQuicSession().newConnection(endPoint);
@@ -219,6 +254,7 @@ public abstract class ProtocolSession extends ContainerLifeCycle
             {
                 processWritableStreams();
                 boolean loop = processReadableStreams();
+                processDatagrams();
 
                 task = poll();
                 if (LOG.isDebugEnabled())
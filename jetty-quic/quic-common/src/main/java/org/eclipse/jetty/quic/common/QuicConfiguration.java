@@ -33,6 +33,10 @@ public class QuicConfiguration
     private int bidirectionalStreamRecvWindow;
     private int unidirectionalStreamRecvWindow;
     private Path pemWorkDirectory;
+    private long maxIdleTimeout;
+    private int initialCongestionWindowPackets;
+    private boolean datagramsEnabled;
+    private boolean earlyDataEnabled;
     private final Map<String, Object> implementationConfiguration = new HashMap<>();
 
     public List<String> getProtocols()
@@ -115,6 +119,88 @@ public class QuicConfiguration
         this.pemWorkDirectory = pemWorkDirectory;
     }
 
+    /**
+     * @return the QUIC {@code max_idle_timeout} transport parameter in milliseconds,
+     * or 0 if the idle timeout is not managed by the QUIC implementation
+     */
+    public long getMaxIdleTimeout()
+    {
+        return maxIdleTimeout;
+    }
+
+    /**
+     * <p>Sets the QUIC {@code max_idle_timeout} transport parameter.</p>
+     * <p>By default the idle timeout is not advertised to the peer, and it is
+     * managed by Jetty via the {@link QuicSession} idle timeout.</p>
+     *
+     * @param maxIdleTimeout the max idle timeout in milliseconds, or 0 to not advertise it
+     */
+    public void setMaxIdleTimeout(long maxIdleTimeout)
+    {
+        this.maxIdleTimeout = maxIdleTimeout;
+    }
+
+    /**
+     * @return the initial congestion window in packets, or 0 to use the QUIC implementation default
+     */
+    public int getInitialCongestionWindowPackets()
+    {
+        return initialCongestionWindowPackets;
+    }
+
+    /**
+     * <p>Sets the initial congestion window in packets.</p>
+     * <p>Not all versions of the QUIC implementation support this parameter,
+     * in which case it is ignored with a warning.</p>
+     *
+     * @param initialCongestionWindowPackets the initial congestion window in packets, or 0 to use the default
+     */
+    public void setInitialCongestionWindowPackets(int initialCongestionWindowPackets)
+    {
+        this.initialCongestionWindowPackets = initialCongestionWindowPackets;
+    }
+
+    /**
+     * @return whether the QUIC {@code max_datagram_frame_size} transport parameter is advertised
+     */
+    public boolean isDatagramsEnabled()
+    {
+        return datagramsEnabled;
+    }
+
+    /**
+     * <p>Sets whether support for unreliable datagrams (RFC 9221) is advertised to the peer.</p>
+     * <p>When negotiated, datagrams can be sent via {@link QuicSession#sendDatagram(java.nio.ByteBuffer)}
+     * and are received by {@link ProtocolSession}.</p>
+     *
+     * @param datagramsEnabled whether datagram support is advertised
+     */
+    public void setDatagramsEnabled(boolean datagramsEnabled)
+    {
+        this.datagramsEnabled = datagramsEnabled;
+    }
+
+    /**
+     * @return whether 0-RTT early data is enabled
+     */
+    public boolean isEarlyDataEnabled()
+    {
+        return earlyDataEnabled;
+    }
+
+    /**
+     * <p>Sets whether 0-RTT early data is enabled.</p>
+     * <p>Early data is only possible when a TLS session from a previous connection
+     * to the same peer is resumed; early data may be replayed by an attacker, so
+     * applications must only send requests that are safe to replay.</p>
+     *
+     * @param earlyDataEnabled whether 0-RTT early data is enabled
+     */
+    public void setEarlyDataEnabled(boolean earlyDataEnabled)
+    {
+        this.earlyDataEnabled = earlyDataEnabled;
+    }
+
     public Map<String, Object> getImplementationConfiguration()
     {
         return implementationConfiguration;
@@ -210,6 +210,23 @@ public abstract class QuicConnection extends AbstractConnection
 
     protected abstract QuicSession createSession(SocketAddress remoteAddress, ByteBuffer cipherBuffer) throws IOException;
 
+    /**
+     * <p>Associates the given session with the given connection ID, so that
+     * received packets for that connection ID are processed by the session,
+     * and starts the session.</p>
+     *
+     * @param quicheConnectionId the connection ID
+     * @param session the session
+     */
+    protected void addSession(QuicheConnectionId quicheConnectionId, QuicSession session)
+    {
+        session.setConnectionId(quicheConnectionId);
+        session.setIdleTimeout(getEndPoint().getIdleTimeout());
+        sessions.put(quicheConnectionId, session);
+        listeners.forEach(session::addEventListener);
+        LifeCycle.start(session);
+    }
+
     public void write(Callback callback, SocketAddress remoteAddress, ByteBuffer... buffers)
     {
         flusher.offer(callback, remoteAddress, buffers);
@@ -270,11 +287,7 @@ public abstract class QuicConnection extends AbstractConnection
                     {
                         if (LOG.isDebugEnabled())
                             LOG.debug("session created");
-                        session.setConnectionId(quicheConnectionId);
-                        session.setIdleTimeout(getEndPoint().getIdleTimeout());
-                        sessions.put(quicheConnectionId, session);
-                        listeners.forEach(session::addEventListener);
-                        LifeCycle.start(session);
+                        addSession(quicheConnectionId, session);
 
                         // Session creation may have generated a task.
                         Runnable task = session.pollTask();
@@ -73,6 +73,8 @@ public abstract class QuicSession extends ContainerLifeCycle
     private volatile ProtocolSession protocolSession;
     private QuicheConnectionId quicheConnectionId;
     private long idleTimeout;
+    private volatile boolean earlyData;
+    private boolean handshakeCompleted;
 
     protected QuicSession(Executor executor, Scheduler scheduler, ByteBufferPool byteBufferPool, QuicheConnection quicheConnection, QuicConnection connection, SocketAddress remoteAddress)
     {
@@ -234,6 +236,42 @@ public abstract class QuicSession extends ContainerLifeCycle
         return flushed;
     }
 
+    /**
+     * @return the max length of a datagram that can be sent, or -1 if datagrams
+     * have not been negotiated with the peer
+     * @see QuicConfiguration#setDatagramsEnabled(boolean)
+     */
+    public int getMaxDatagramLength()
+    {
+        return quicheConnection.maxDatagramLength();
+    }
+
+    /**
+     * <p>Queues the given buffer to be sent as a single, unreliable, QUIC DATAGRAM frame (RFC 9221).</p>
+     *
+     * @param buffer the datagram bytes
+     * @return true if the datagram has been queued, false if the datagram send queue is full
+     * @throws IOException if the datagram cannot be sent, for example because it is larger
+     * than {@link #getMaxDatagramLength()}
+     */
+    public boolean sendDatagram(ByteBuffer buffer) throws IOException
+    {
+        boolean queued = quicheConnection.feedClearBytesForDatagram(buffer) > 0 || !buffer.hasRemaining();
+        flush();
+        return queued;
+    }
+
+    ByteBuffer receiveDatagram() throws IOException
+    {
+        int length = quicheConnection.nextDatagramLength();
+        if (length < 0)
+            return null;
+        ByteBuffer datagram = ByteBuffer.allocate(length);
+        if (quicheConnection.drainClearBytesForDatagram(datagram) < 0)
+            return null;
+        return datagram.flip();
+    }
+
     public boolean isFinished(long streamId)
     {
         return quicheConnection.isStreamFinished(streamId);
@@ -287,6 +325,41 @@ public abstract class QuicSession extends ContainerLifeCycle
         return quicheConnection.isConnectionEstablished();
     }
 
+    /**
+     * @return whether the connection is in 0-RTT early data, that is
+     * a TLS session has been resumed but the handshake is not yet complete
+     */
+    public boolean isConnectionInEarlyData()
+    {
+        return quicheConnection.isConnectionInEarlyData();
+    }
+
+    /**
+     * @return whether the TLS session of the connection was resumed from a previous session
+     */
+    public boolean isResumed()
+    {
+        return quicheConnection.isResumed();
+    }
+
+    /**
+     * @return whether the {@link ProtocolSession} was created while in 0-RTT early data
+     * @see #processEarlyData()
+     */
+    public boolean isEarlyData()
+    {
+        return earlyData;
+    }
+
+    /**
+     * @return the serialized TLS session that can be used to resume this session,
+     * or null if no session ticket has been received from the server
+     */
+    public byte[] getTlsSession()
+    {
+        return quicheConnection.getSession();
+    }
+
     public QuicheConnectionId getConnectionId()
     {
         return quicheConnectionId;
@@ -299,10 +372,6 @@ public abstract class QuicSession extends ContainerLifeCycle
 
     public Runnable process(SocketAddress remoteAddress, ByteBuffer cipherBufferIn) throws IOException
     {
-        // While the connection ID remains the same,
-        // the remote address may change so store it again.
-        this.remoteAddress = remoteAddress;
-
         int remaining = cipherBufferIn.remaining();
         if (LOG.isDebugEnabled())
             LOG.debug("feeding {} cipher bytes to {}", remaining, this);
@@ -310,13 +379,35 @@ public abstract class QuicSession extends ContainerLifeCycle
         if (accepted != remaining)
             throw new IllegalStateException();
 
+        // While the connection ID remains the same, the remote address may
+        // change due to NAT rebinding or connection migration.
+        // Quiche drops packets that cannot be decrypted while still reporting
+        // them as consumed, so only switch to the new address once Quiche has
+        // made it the active path, so that off-path attackers cannot redirect
+        // the session.
+        SocketAddress oldRemoteAddress = this.remoteAddress;
+        if (!remoteAddress.equals(oldRemoteAddress) && quicheConnection.isActivePeerAddress(remoteAddress))
+        {
+            this.remoteAddress = remoteAddress;
+            if (LOG.isDebugEnabled())
+                LOG.debug("remote address changed from {} to {} on {}", oldRemoteAddress, remoteAddress, this);
+            getEventListeners().stream()
+                .filter(Listener.class::isInstance)
+                .map(Listener.class::cast)
+                .forEach(listener -> notifyRemoteAddressChanged(listener, oldRemoteAddress, remoteAddress));
+        }
+
         if (isConnectionEstablished())
         {
-            ProtocolSession protocol = protocolSession;
-            if (protocol == null)
+            ProtocolSession protocol = getOrCreateProtocolSession();
+
+            if (!handshakeCompleted)
             {
-                protocolSession = protocol = createProtocolSession();
-                addManaged(protocol);
+                handshakeCompleted = true;
+                getEventListeners().stream()
+                    .filter(Listener.class::isInstance)
+                    .map(Listener.class::cast)
+                    .forEach(this::notifyHandshakeCompleted);
             }
 
             if (LOG.isDebugEnabled())
@@ -331,6 +422,59 @@ public abstract class QuicSession extends ContainerLifeCycle
         }
     }
 
+    /**
+     * <p>Creates the {@link ProtocolSession} before the QUIC handshake is complete,
+     * so that the protocol can send application data as 0-RTT early data.</p>
+     *
+     * @return a task that processes the protocol session,
+     * or null if the connection is not in early data
+     */
+    public Runnable processEarlyData()
+    {
+        if (!isConnectionInEarlyData())
+            return null;
+        earlyData = true;
+        ProtocolSession protocol = getOrCreateProtocolSession();
+        if (LOG.isDebugEnabled())
+            LOG.debug("processing early data {}", protocol);
+        return protocol.getProducerTask();
+    }
+
+    private ProtocolSession getOrCreateProtocolSession()
+    {
+        ProtocolSession protocol = protocolSession;
+        if (protocol == null)
+        {
+            protocolSession = protocol = createProtocolSession();
+            addManaged(protocol);
+        }
+        return protocol;
+    }
+
+    private void notifyHandshakeCompleted(Listener listener)
+    {
+        try
+        {
+            listener.onHandshakeCompleted(this);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyRemoteAddressChanged(Listener listener, SocketAddress oldAddress, SocketAddress newAddress)
+    {
+        try
+        {
+            listener.onRemoteAddressChanged(this, oldAddress, newAddress);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
     // TODO: this is ugly, is there a better solution?
     protected Runnable pollTask()
     {
@@ -550,5 +694,35 @@ public abstract class QuicSession extends ContainerLifeCycle
         public default void onClosed(QuicSession session)
         {
         }
+
+        /**
+         * <p>Callback method invoked when the remote peer of a {@link QuicSession}
+         * sends packets from a different address, either because of NAT rebinding
+         * (typically only the port changes) or because of connection migration
+         * (for example a mobile client roaming between networks).</p>
+         * <p>The session keeps working across the address change; this method is
+         * only invoked after Quiche has authenticated a non-probing packet from the
+         * new address and has made it the active path of the connection.
+         * Packets received from other addresses that Quiche does not authenticate,
+         * or that only probe a new path, do not change the remote address.</p>
+         *
+         * @param session the session
+         * @param oldAddress the previous remote address
+         * @param newAddress the new remote address
+         */
+        public default void onRemoteAddressChanged(QuicSession session, SocketAddress oldAddress, SocketAddress newAddress)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the QUIC handshake of a {@link QuicSession} is complete.</p>
+         * <p>For sessions that sent 0-RTT early data (see {@link QuicSession#isEarlyData()}),
+         * this is the point after which application data is no longer subject to replay.</p>
+         *
+         * @param session the session
+         */
+        public default void onHandshakeCompleted(QuicSession session)
+        {
+        }
     }
 }
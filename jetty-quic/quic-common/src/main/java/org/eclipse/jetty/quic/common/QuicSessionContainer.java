@@ -44,6 +44,14 @@ public class QuicSessionContainer extends AbstractLifeCycle implements QuicSessi
         sessions.remove(session);
     }
 
+    /**
+     * @return the number of sessions currently tracked
+     */
+    public int getSessionCount()
+    {
+        return sessions.size();
+    }
+
     @Override
     public CompletableFuture<Void> shutdown()
     {
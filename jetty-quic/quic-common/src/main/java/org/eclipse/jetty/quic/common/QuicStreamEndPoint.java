@@ -26,6 +26,8 @@ import org.eclipse.jetty.io.FillInterest;
 import org.eclipse.jetty.io.WriteFlusher;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.AutoLock;
 import org.eclipse.jetty.util.thread.Scheduler;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
@@ -40,8 +42,13 @@ public class QuicStreamEndPoint extends AbstractEndPoint
     private static final Logger LOG = LoggerFactory.getLogger(QuicStreamEndPoint.class);
     private static final ByteBuffer LAST_FLAG = ByteBuffer.allocate(0);
 
+    private final AutoLock lock = new AutoLock();
     private final QuicSession session;
     private final long streamId;
+    private long flushStalls;
+    private long flushStallNanos;
+    private long flushStallNanoTime;
+    private boolean flushStalled;
 
     public QuicStreamEndPoint(Scheduler scheduler, QuicSession session, long streamId)
     {
@@ -63,6 +70,7 @@ public class QuicStreamEndPoint extends AbstractEndPoint
     {
         if (LOG.isDebugEnabled())
             LOG.debug("closed {}", this);
+        onFlushUnstalled();
         Connection connection = getConnection();
         if (connection != null)
             connection.onClose(failure);
@@ -144,6 +152,7 @@ public class QuicStreamEndPoint extends AbstractEndPoint
             writeFlusher.onFail(failure);
 
         session.remove(this, failure);
+        onFlushUnstalled();
 
         if (LOG.isDebugEnabled())
             LOG.debug("closed with error 0x{} {}", Long.toHexString(error), this, failure);
@@ -189,14 +198,89 @@ public class QuicStreamEndPoint extends AbstractEndPoint
             {
                 if (LOG.isDebugEnabled())
                     LOG.debug("incomplete flushing of {}", this);
+                onFlushStalled();
                 return false;
             }
         }
         if (LOG.isDebugEnabled())
             LOG.debug("flushed {}", this);
+        onFlushUnstalled();
         return true;
     }
 
+    private void onFlushStalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (flushStalled)
+                return;
+            flushStalled = true;
+            ++flushStalls;
+            flushStallNanoTime = NanoTime.now();
+        }
+    }
+
+    private void onFlushUnstalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (!flushStalled)
+                return;
+            flushStalled = false;
+            flushStallNanos += NanoTime.since(flushStallNanoTime);
+        }
+    }
+
+    /**
+     * @return whether writes are currently stalled, typically by QUIC flow control
+     */
+    public boolean isFlushStalled()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return flushStalled;
+        }
+    }
+
+    /**
+     * @return the number of times writes have been stalled, typically by QUIC flow control
+     */
+    public long getFlushStalls()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return flushStalls;
+        }
+    }
+
+    /**
+     * @return the total time in nanoseconds writes have been stalled, including the current stall
+     */
+    public long getFlushStallNanos()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return flushStalled ? flushStallNanos + NanoTime.since(flushStallNanoTime) : flushStallNanos;
+        }
+    }
+
+    /**
+     * <p>Returns the earliest nanoTime at which the total stall time may
+     * exceed the given maximum, that is assuming that writes remain stalled
+     * if they are currently stalled, or that they are stalled from now on.</p>
+     *
+     * @param maxStallNanos the maximum stall time in nanoseconds
+     * @return the earliest nanoTime at which the maximum stall time may be exceeded
+     */
+    public long getFlushStallExpireNanoTime(long maxStallNanos)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            long beginNanoTime = flushStalled ? flushStallNanoTime : NanoTime.now();
+            return beginNanoTime + Math.max(0, maxStallNanos - flushStallNanos);
+        }
+    }
+
     public void write(Callback callback, List<ByteBuffer> buffers, boolean last)
     {
         ByteBuffer[] array;
@@ -25,6 +25,6 @@ public interface QuicheBinding
 
     byte[] fromPacket(ByteBuffer packet);
     QuicheConnection connect(QuicheConfig quicheConfig, InetSocketAddress local, InetSocketAddress peer, int connectionIdLength) throws IOException;
-    boolean negotiate(QuicheConnection.TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException;
+    boolean negotiate(QuicheConnection.TokenMinter tokenMinter, QuicheConnection.ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException;
     QuicheConnection tryAccept(QuicheConfig quicheConfig, QuicheConnection.TokenValidator tokenValidator, ByteBuffer packetRead, SocketAddress local, SocketAddress peer) throws IOException;
 }
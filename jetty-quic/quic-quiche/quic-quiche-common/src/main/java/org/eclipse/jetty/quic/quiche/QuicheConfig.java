@@ -15,6 +15,8 @@ package org.eclipse.jetty.quic.quiche;
 
 public class QuicheConfig
 {
+    public static final long DEFAULT_DGRAM_QUEUE_LEN = 1024;
+
     public enum CongestionControl
     {
         RENO(Quiche.quiche_cc_algorithm.QUICHE_CC_RENO),
@@ -51,6 +53,13 @@ public class QuicheConfig
     private Long maxConnectionWindow;
     private Long maxStreamWindow;
     private Long activeConnectionIdLimit;
+    private Boolean enableEarlyData;
+    private Boolean enableDgram;
+    private Long dgramRecvQueueLen;
+    private Long dgramSendQueueLen;
+    private Long initialCongestionWindowPackets;
+    private String keyLogPath;
+    private byte[] ticketKey;
 
     public QuicheConfig()
     {
@@ -146,6 +155,41 @@ public class QuicheConfig
         return activeConnectionIdLimit;
     }
 
+    public Boolean getEnableEarlyData()
+    {
+        return enableEarlyData;
+    }
+
+    public Boolean getEnableDgram()
+    {
+        return enableDgram;
+    }
+
+    public Long getDgramRecvQueueLen()
+    {
+        return dgramRecvQueueLen;
+    }
+
+    public Long getDgramSendQueueLen()
+    {
+        return dgramSendQueueLen;
+    }
+
+    public Long getInitialCongestionWindowPackets()
+    {
+        return initialCongestionWindowPackets;
+    }
+
+    public String getKeyLogPath()
+    {
+        return keyLogPath;
+    }
+
+    public byte[] getTicketKey()
+    {
+        return ticketKey;
+    }
+
     public void setVersion(int version)
     {
         this.version = version;
@@ -235,4 +279,50 @@ public class QuicheConfig
     {
         this.activeConnectionIdLimit = activeConnectionIdLimit;
     }
+
+    public void setEnableEarlyData(Boolean enable)
+    {
+        this.enableEarlyData = enable;
+    }
+
+    public void setEnableDgram(Boolean enable)
+    {
+        this.enableDgram = enable;
+    }
+
+    public void setDgramRecvQueueLen(Long queueLength)
+    {
+        this.dgramRecvQueueLen = queueLength;
+    }
+
+    public void setDgramSendQueueLen(Long queueLength)
+    {
+        this.dgramSendQueueLen = queueLength;
+    }
+
+    public void setInitialCongestionWindowPackets(Long packets)
+    {
+        this.initialCongestionWindowPackets = packets;
+    }
+
+    /**
+     * <p>Sets the file where TLS key material is appended in NSS key log format, for debugging.</p>
+     *
+     * @param path the key log file path, or null to disable key logging
+     */
+    public void setKeyLogPath(String path)
+    {
+        this.keyLogPath = path;
+    }
+
+    /**
+     * <p>Sets the key material used to encrypt and decrypt TLS session tickets.</p>
+     * <p>Servers sharing the same ticket key can resume the TLS sessions issued by each other.</p>
+     *
+     * @param ticketKey the 48 bytes ticket key material, or null to use a random key
+     */
+    public void setTicketKey(byte[] ticketKey)
+    {
+        this.ticketKey = ticketKey;
+    }
 }
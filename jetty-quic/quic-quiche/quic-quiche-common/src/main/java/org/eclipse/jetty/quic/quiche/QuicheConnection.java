@@ -49,6 +49,8 @@ public abstract class QuicheConnection
             LOG.debug("using quiche binding implementation: {}", QUICHE_BINDING.getClass().getName());
     }
 
+    private QuicheConnectionId sourceConnectionId;
+
     public static QuicheConnection connect(QuicheConfig quicheConfig, InetSocketAddress local, InetSocketAddress peer) throws IOException
     {
         return connect(quicheConfig, local, peer, Quiche.QUICHE_MAX_CONN_ID_LEN);
@@ -66,7 +68,19 @@ public abstract class QuicheConnection
      */
     public static boolean negotiate(TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
     {
-        return QUICHE_BINDING.negotiate(tokenMinter, packetRead, packetToSend);
+        return negotiate(tokenMinter, null, packetRead, packetToSend);
+    }
+
+    /**
+     * Fully consumes the {@code packetRead} buffer.
+     * @param connectionIdGenerator the generator of the server connection ID sent in a retry packet,
+     * or null to use a random connection ID.
+     * @return true if a negotiation packet was written to the {@code packetToSend} buffer, false if negotiation failed
+     * and the {@code packetRead} buffer can be dropped.
+     */
+    public static boolean negotiate(TokenMinter tokenMinter, ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
+    {
+        return QUICHE_BINDING.negotiate(tokenMinter, connectionIdGenerator, packetRead, packetToSend);
     }
 
     /**
@@ -78,6 +92,20 @@ public abstract class QuicheConnection
         return QUICHE_BINDING.tryAccept(quicheConfig, tokenValidator, packetRead, local, peer);
     }
 
+    /**
+     * @return the source connection ID of a client connection, that is the destination
+     * connection ID of the packets sent by the server, or null for a server connection.
+     */
+    public QuicheConnectionId getSourceConnectionId()
+    {
+        return sourceConnectionId;
+    }
+
+    protected void setSourceConnectionId(byte[] scid)
+    {
+        this.sourceConnectionId = QuicheConnectionId.fromBytes(scid);
+    }
+
     public final List<Long> readableStreamIds()
     {
         return iterableStreamIds(false);
@@ -108,10 +136,49 @@ public abstract class QuicheConnection
      */
     public abstract int drainCipherBytes(ByteBuffer buffer) throws IOException;
 
+    /**
+     * <p>Returns whether the given address is the peer address of the active path.</p>
+     * <p>Quiche switches the active path only after it has authenticated a non-probing
+     * packet received from a new peer address, while packets that cannot be decrypted are
+     * silently dropped by {@link #feedCipherBytes(ByteBuffer, SocketAddress, SocketAddress)}.</p>
+     * @param peer the peer address to check.
+     * @return whether the given address is the peer address of the active path.
+     */
+    public abstract boolean isActivePeerAddress(SocketAddress peer);
+
     public abstract boolean isConnectionClosed();
 
     public abstract boolean isConnectionEstablished();
 
+    /**
+     * @return true if the handshake is in progress and has progressed enough
+     * to send or receive early (0-RTT) data.
+     */
+    public abstract boolean isConnectionInEarlyData();
+
+    /**
+     * <p>Returns the serialized TLS session of this connection, that may be used with
+     * {@link #setSession(byte[])} on a new connection to the same server to resume the
+     * TLS session and possibly send early (0-RTT) data.</p>
+     * <p>The session is only available after the server sent a session ticket,
+     * which may happen some time after the handshake is complete.</p>
+     * @return the serialized TLS session, or null if no session is available.
+     */
+    public abstract byte[] getSession();
+
+    /**
+     * <p>Configures the TLS session to resume, as obtained from {@link #getSession()}.</p>
+     * <p>This method must be called on a client connection before any packet is drained.</p>
+     * @param session the serialized TLS session.
+     * @return true if the session was set, false if the session could not be parsed.
+     */
+    public abstract boolean setSession(byte[] session);
+
+    /**
+     * @return true if the TLS session of this connection was resumed from a previous session.
+     */
+    public abstract boolean isResumed();
+
     public abstract long nextTimeout();
 
     public abstract void onTimeout();
@@ -148,6 +215,33 @@ public abstract class QuicheConnection
 
     public abstract boolean isStreamFinished(long streamId);
 
+    /**
+     * @return the max length of a datagram that can be sent, or -1 if the peer
+     * does not support datagrams or the connection is not yet established.
+     */
+    public abstract int maxDatagramLength();
+
+    /**
+     * @return the length of the first datagram in the receive queue, or -1 if the queue is empty.
+     */
+    public abstract int nextDatagramLength();
+
+    /**
+     * Queue the given buffer as a single unreliable datagram to be sent.
+     * @param buffer the buffer containing the datagram bytes.
+     * @return how many bytes were consumed, or 0 if the datagram send queue is full.
+     * @throws IOException if the datagram cannot be sent, for example because it is too large.
+     */
+    public abstract int feedClearBytesForDatagram(ByteBuffer buffer) throws IOException;
+
+    /**
+     * Fill the given buffer with the first datagram from the receive queue.
+     * @param buffer the buffer to fill, possibly sized via {@link #nextDatagramLength()}.
+     * @return how many bytes were added to the buffer, or -1 if no datagram was available.
+     * @throws IOException if the datagram cannot be read, for example because the buffer is too small.
+     */
+    public abstract int drainClearBytesForDatagram(ByteBuffer buffer) throws IOException;
+
     public abstract CloseInfo getRemoteCloseInfo();
 
     public abstract CloseInfo getLocalCloseInfo();
@@ -183,6 +277,25 @@ public abstract class QuicheConnection
     public interface TokenValidator
     {
         byte[] validate(byte[] token, int len);
+
+        /**
+         * @return whether an Initial packet without token must be answered with
+         * a Retry packet, rather than accepted without address validation
+         */
+        default boolean isRetryRequired()
+        {
+            return true;
+        }
+    }
+
+    /**
+     * <p>Generates the connection IDs chosen by the server, for example to
+     * encode routing information for a load balancer in them.</p>
+     * <p>Generated connection IDs must be {@link Quiche#QUICHE_MAX_CONN_ID_LEN} bytes long.</p>
+     */
+    public interface ConnectionIdGenerator
+    {
+        byte[] generate();
     }
 
     public static class TokenValidationException extends IOException
@@ -36,6 +36,11 @@ public class QuicheConnectionId
         this.hashCode = Arrays.hashCode(dcid);
     }
 
+    static QuicheConnectionId fromBytes(byte[] bytes)
+    {
+        return new QuicheConnectionId(bytes.clone());
+    }
+
     /**
      * Does not consume the packet byte buffer.
      */
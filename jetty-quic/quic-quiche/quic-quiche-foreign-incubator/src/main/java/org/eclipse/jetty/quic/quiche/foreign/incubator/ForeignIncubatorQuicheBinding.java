@@ -63,9 +63,9 @@ public class ForeignIncubatorQuicheBinding implements QuicheBinding
     }
 
     @Override
-    public boolean negotiate(QuicheConnection.TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
+    public boolean negotiate(QuicheConnection.TokenMinter tokenMinter, QuicheConnection.ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
     {
-        return ForeignIncubatorQuicheConnection.negotiate(tokenMinter, packetRead, packetToSend);
+        return ForeignIncubatorQuicheConnection.negotiate(tokenMinter, connectionIdGenerator, packetRead, packetToSend);
     }
 
     @Override
@@ -22,6 +22,7 @@ import java.nio.ByteOrder;
 import java.nio.charset.StandardCharsets;
 import java.security.SecureRandom;
 import java.util.ArrayList;
+import java.util.Arrays;
 import java.util.List;
 
 import jdk.incubator.foreign.CLinker;
@@ -152,6 +153,8 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
             MemorySegment peerSockaddr = sockaddr.convert(peer, scope);
             MemoryAddress quicheConn = quiche_h.quiche_connect(CLinker.toCString(peer.getHostString(), scope), scid, scid.byteSize(), localSockaddr, localSockaddr.byteSize(), peerSockaddr, peerSockaddr.byteSize(), libQuicheConfig);
             ForeignIncubatorQuicheConnection connection = new ForeignIncubatorQuicheConnection(quicheConn, libQuicheConfig, scope);
+            connection.setSourceConnectionId(scidBytes);
+            connection.enableKeyLog(quicheConfig.getKeyLogPath());
             keepScope = true;
             return connection;
         }
@@ -260,10 +263,58 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         if (activeConnectionIdLimit != null)
             quiche_h.quiche_config_set_active_connection_id_limit(quicheConfig, activeConnectionIdLimit);
 
+        Boolean enableEarlyData = config.getEnableEarlyData();
+        if (enableEarlyData != null && enableEarlyData)
+            quiche_h.quiche_config_enable_early_data(quicheConfig);
+
+        Boolean enableDgram = config.getEnableDgram();
+        if (enableDgram != null)
+        {
+            Long recvQueueLen = config.getDgramRecvQueueLen();
+            Long sendQueueLen = config.getDgramSendQueueLen();
+            quiche_h.quiche_config_enable_dgram(quicheConfig, enableDgram ? C_TRUE : C_FALSE,
+                recvQueueLen == null ? QuicheConfig.DEFAULT_DGRAM_QUEUE_LEN : recvQueueLen,
+                sendQueueLen == null ? QuicheConfig.DEFAULT_DGRAM_QUEUE_LEN : sendQueueLen);
+        }
+
+        Long initialCongestionWindowPackets = config.getInitialCongestionWindowPackets();
+        if (initialCongestionWindowPackets != null)
+        {
+            if (quiche_h.quiche_config_has_initial_congestion_window_packets())
+                quiche_h.quiche_config_set_initial_congestion_window_packets(quicheConfig, initialCongestionWindowPackets);
+            else
+                LOG.warn("initial congestion window not supported by native quiche library, ignoring");
+        }
+
+        if (config.getKeyLogPath() != null)
+            quiche_h.quiche_config_log_keys(quicheConfig);
+
+        byte[] ticketKey = config.getTicketKey();
+        if (ticketKey != null)
+        {
+            if (quiche_h.quiche_config_has_ticket_key())
+            {
+                MemorySegment segment = MemorySegment.allocateNative(ticketKey.length, scope);
+                segment.asByteBuffer().put(ticketKey);
+                int rc = quiche_h.quiche_config_set_ticket_key(quicheConfig, segment.address(), segment.byteSize());
+                if (rc < 0)
+                    throw new IOException("Error setting ticket key : " + Quiche.quiche_error.errToString(rc));
+            }
+            else
+            {
+                LOG.warn("ticket key not supported by native quiche library, ignoring");
+            }
+        }
+
         return quicheConfig;
     }
 
     public static boolean negotiate(TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
+    {
+        return negotiate(tokenMinter, null, packetRead, packetToSend);
+    }
+
+    public static boolean negotiate(TokenMinter tokenMinter, ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
     {
         try (ResourceScope scope = ResourceScope.newConfinedScope())
         {
@@ -350,8 +401,7 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
                 byte[] tokenBytes = tokenMinter.mint(dcidBytes, dcidBytes.length);
                 token.asByteBuffer().put(tokenBytes);
 
-                byte[] newCid = new byte[QUICHE_MAX_CONN_ID_LEN];
-                SECURE_RANDOM.nextBytes(newCid);
+                byte[] newCid = newConnectionId(connectionIdGenerator);
                 MemorySegment newCidSegment = MemorySegment.allocateNative(newCid.length, scope);
                 newCidSegment.asByteBuffer().put(newCid);
 
@@ -387,6 +437,20 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         }
     }
 
+    private static byte[] newConnectionId(ConnectionIdGenerator connectionIdGenerator)
+    {
+        if (connectionIdGenerator != null)
+        {
+            byte[] cid = connectionIdGenerator.generate();
+            if (cid.length != QUICHE_MAX_CONN_ID_LEN)
+                throw new IllegalStateException("invalid connection ID length " + cid.length + " from " + connectionIdGenerator);
+            return cid;
+        }
+        byte[] cid = new byte[QUICHE_MAX_CONN_ID_LEN];
+        SECURE_RANDOM.nextBytes(cid);
+        return cid;
+    }
+
     public static ForeignIncubatorQuicheConnection tryAccept(QuicheConfig quicheConfig, TokenValidator tokenValidator, ByteBuffer packetRead, SocketAddress local, SocketAddress peer) throws IOException
     {
         boolean keepScope = false;
@@ -454,30 +518,40 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
                 return null;
             }
 
+            // Original Destination Connection ID
+            MemoryAddress odcid = MemoryAddress.NULL;
+            long odcidLen = 0;
             int tokenLen = (int)getLong(token_len);
             if (tokenLen == 0)
             {
-                LOG.debug("need stateless retry");
-                return null;
+                if (tokenValidator.isRetryRequired())
+                {
+                    LOG.debug("need stateless retry");
+                    return null;
+                }
+                LOG.debug("accepting without address validation");
+            }
+            else
+            {
+                LOG.debug("token validation...");
+                byte[] tokenBytes = new byte[(int)token.byteSize()];
+                token.asByteBuffer().get(tokenBytes, 0, tokenLen);
+                byte[] odcidBytes = tokenValidator.validate(tokenBytes, tokenLen);
+                if (odcidBytes == null)
+                    throw new TokenValidationException("invalid address validation token");
+                LOG.debug("validated token");
+                MemorySegment odcidSegment = MemorySegment.allocateNative(odcidBytes.length, scope);
+                odcidSegment.asByteBuffer().put(odcidBytes);
+                odcid = odcidSegment.address();
+                odcidLen = odcidSegment.byteSize();
             }
-
-            LOG.debug("token validation...");
-            // Original Destination Connection ID
-            byte[] tokenBytes = new byte[(int)token.byteSize()];
-            token.asByteBuffer().get(tokenBytes, 0, tokenLen);
-            byte[] odcidBytes = tokenValidator.validate(tokenBytes, tokenLen);
-            if (odcidBytes == null)
-                throw new TokenValidationException("invalid address validation token");
-            LOG.debug("validated token");
-            MemorySegment odcid = MemorySegment.allocateNative(odcidBytes.length, scope);
-            odcid.asByteBuffer().put(odcidBytes);
 
             LOG.debug("connection creation...");
             MemoryAddress libQuicheConfig = buildConfig(quicheConfig, scope);
 
             MemorySegment localSockaddr = sockaddr.convert(local, scope);
             MemorySegment peerSockaddr = sockaddr.convert(peer, scope);
-            MemoryAddress quicheConn = quiche_h.quiche_accept(dcid.address(), getLong(dcid_len), odcid.address(), odcid.byteSize(), localSockaddr.address(), localSockaddr.byteSize(), peerSockaddr.address(), peerSockaddr.byteSize(), libQuicheConfig);
+            MemoryAddress quicheConn = quiche_h.quiche_accept(dcid.address(), getLong(dcid_len), odcid, odcidLen, localSockaddr.address(), localSockaddr.byteSize(), peerSockaddr.address(), peerSockaddr.byteSize(), libQuicheConfig);
             if (quicheConn == null)
             {
                 quiche_h.quiche_config_free(libQuicheConfig);
@@ -486,6 +560,7 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
 
             LOG.debug("connection created");
             ForeignIncubatorQuicheConnection quicheConnection = new ForeignIncubatorQuicheConnection(quicheConn, libQuicheConfig, scope);
+            quicheConnection.enableKeyLog(quicheConfig.getKeyLogPath());
             LOG.debug("accepted, immediately receiving the same packet - remaining in buffer: {}", packetRead.remaining());
             while (packetRead.hasRemaining())
             {
@@ -501,6 +576,20 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         }
     }
 
+    private void enableKeyLog(String keyLogPath)
+    {
+        if (keyLogPath == null)
+            return;
+        try (AutoLock ignore = lock.lock())
+        {
+            try (ResourceScope scope = ResourceScope.newConfinedScope())
+            {
+                if (quiche_h.quiche_conn_set_keylog_path(quicheConn, CLinker.toCString(keyLogPath, scope).address()) == C_FALSE)
+                    LOG.warn("Unable to set TLS key log path to {}", keyLogPath);
+            }
+        }
+    }
+
     public byte[] getPeerCertificate()
     {
         try (AutoLock ignore = lock.lock())
@@ -594,6 +683,32 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public boolean isActivePeerAddress(SocketAddress peer)
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            byte[] peerBytes;
+            try (ResourceScope scope = ResourceScope.newConfinedScope())
+            {
+                peerBytes = sockaddr.convert(peer, scope).toByteArray();
+            }
+            quiche_h.quiche_conn_stats(quicheConn, stats.address());
+            long pathsCount = quiche_stats.get_paths_count(stats);
+            for (long i = 0; i < pathsCount; ++i)
+            {
+                if (quiche_h.quiche_conn_path_stats(quicheConn, i, pathStats.address()) != 0)
+                    continue;
+                if (!quiche_path_stats.get_active(pathStats))
+                    continue;
+                return Arrays.equals(peerBytes, quiche_path_stats.get_peer_addr(pathStats));
+            }
+            return false;
+        }
+    }
+
     @Override
     public int drainCipherBytes(ByteBuffer buffer) throws IOException
     {
@@ -653,6 +768,72 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public boolean isConnectionInEarlyData()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            return quiche_h.quiche_conn_is_in_early_data(quicheConn) != C_FALSE;
+        }
+    }
+
+    @Override
+    public byte[] getSession()
+    {
+        try (AutoLock ignore = lock.lock(); ResourceScope scope = ResourceScope.newConfinedScope())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+
+            MemorySegment outSegment = MemorySegment.allocateNative(CLinker.C_POINTER, scope);
+            MemorySegment outLenSegment = MemorySegment.allocateNative(CLinker.C_LONG, scope);
+            quiche_h.quiche_conn_session(quicheConn, outSegment.address(), outLenSegment.address());
+
+            long outLen = getLong(outLenSegment);
+            if (outLen == 0L)
+                return null;
+            byte[] out = new byte[(int)outLen];
+            // dereference outSegment pointer
+            MemoryAddress memoryAddress = MemoryAddress.ofLong(getLong(outSegment));
+            memoryAddress.asSegment(outLen, ResourceScope.globalScope()).asByteBuffer().get(out);
+            return out;
+        }
+    }
+
+    @Override
+    public boolean setSession(byte[] session)
+    {
+        try (AutoLock ignore = lock.lock(); ResourceScope scope = ResourceScope.newConfinedScope())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+
+            MemorySegment sessionSegment = MemorySegment.allocateNative(session.length, scope);
+            sessionSegment.asByteBuffer().put(session);
+            int rc = quiche_h.quiche_conn_set_session(quicheConn, sessionSegment.address(), session.length);
+            if (rc < 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not set session: {}", quiche_error.errToString(rc));
+                return false;
+            }
+            return true;
+        }
+    }
+
+    @Override
+    public boolean isResumed()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            return quiche_h.quiche_conn_is_resumed(quicheConn) != C_FALSE;
+        }
+    }
+
     @Override
     public long nextTimeout()
     {
@@ -914,6 +1095,107 @@ public class ForeignIncubatorQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public int maxDatagramLength()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            long length = quiche_h.quiche_conn_dgram_max_writable_len(quicheConn);
+            return length < 0 ? -1 : (int)length;
+        }
+    }
+
+    @Override
+    public int nextDatagramLength()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            long length = quiche_h.quiche_conn_dgram_recv_front_len(quicheConn);
+            return length < 0 ? -1 : (int)length;
+        }
+    }
+
+    @Override
+    public int feedClearBytesForDatagram(ByteBuffer buffer) throws IOException
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IOException("connection was released");
+
+            long written;
+            if (buffer.isDirect())
+            {
+                // If the ByteBuffer is direct, it can be used without any copy.
+                MemorySegment bufferSegment = MemorySegment.ofByteBuffer(buffer);
+                written = quiche_h.quiche_conn_dgram_send(quicheConn, bufferSegment.address(), buffer.remaining());
+            }
+            else
+            {
+                // If the ByteBuffer is heap-allocated, it must be copied to native memory.
+                try (ResourceScope scope = ResourceScope.newConfinedScope())
+                {
+                    MemorySegment bufferSegment = MemorySegment.allocateNative(Math.max(1, buffer.remaining()), scope);
+                    int prevPosition = buffer.position();
+                    bufferSegment.asByteBuffer().put(buffer);
+                    buffer.position(prevPosition);
+                    written = quiche_h.quiche_conn_dgram_send(quicheConn, bufferSegment.address(), buffer.remaining());
+                }
+            }
+
+            if (written == quiche_error.QUICHE_ERR_DONE)
+                return 0;
+            if (written < 0L)
+                throw new IOException("failed to send datagram; quiche_err=" + quiche_error.errToString(written));
+            buffer.position((int)(buffer.position() + written));
+            return (int)written;
+        }
+    }
+
+    @Override
+    public int drainClearBytesForDatagram(ByteBuffer buffer) throws IOException
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IOException("connection was released");
+
+            long read;
+            if (buffer.isDirect())
+            {
+                // If the ByteBuffer is direct, it can be used without any copy.
+                MemorySegment bufferSegment = MemorySegment.ofByteBuffer(buffer);
+                read = quiche_h.quiche_conn_dgram_recv(quicheConn, bufferSegment.address(), buffer.remaining());
+            }
+            else
+            {
+                // If the ByteBuffer is heap-allocated, native memory must be copied to it.
+                try (ResourceScope scope = ResourceScope.newConfinedScope())
+                {
+                    MemorySegment bufferSegment = MemorySegment.allocateNative(Math.max(1, buffer.remaining()), scope);
+                    read = quiche_h.quiche_conn_dgram_recv(quicheConn, bufferSegment.address(), buffer.remaining());
+                    if (read > 0)
+                    {
+                        int prevPosition = buffer.position();
+                        buffer.put(bufferSegment.asByteBuffer().limit((int)read));
+                        buffer.position(prevPosition);
+                    }
+                }
+            }
+
+            if (read == quiche_error.QUICHE_ERR_DONE)
+                return -1;
+            if (read < 0L)
+                throw new IOException("failed to receive datagram; quiche_err=" + quiche_error.errToString(read));
+            buffer.position((int)(buffer.position() + read));
+            return (int)read;
+        }
+    }
+
     @Override
     public boolean isStreamFinished(long streamId)
     {
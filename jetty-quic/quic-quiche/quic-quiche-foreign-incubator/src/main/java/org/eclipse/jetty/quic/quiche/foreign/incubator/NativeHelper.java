@@ -117,6 +117,17 @@ class NativeHelper
             });
     }
 
+    static MethodHandle optionalDowncallHandle(String name, String desc, FunctionDescriptor fdesc)
+    {
+        return LIBRARIES.lookup(name)
+            .map(addr ->
+            {
+                MethodType mt = MethodType.fromMethodDescriptorString(desc, CLASSLOADER);
+                return LINKER.downcallHandle(addr, mt, fdesc);
+            })
+            .orElse(null);
+    }
+
     static <T> MemoryAddress upcallHandle(Class<T> clazz, T t, String name, String desc, FunctionDescriptor fdesc, ResourceScope scope)
     {
         try
@@ -28,6 +28,7 @@ import static jdk.incubator.foreign.CLinker.C_INT;
 import static jdk.incubator.foreign.CLinker.C_LONG;
 import static jdk.incubator.foreign.CLinker.C_POINTER;
 import static org.eclipse.jetty.quic.quiche.foreign.incubator.NativeHelper.downcallHandle;
+import static org.eclipse.jetty.quic.quiche.foreign.incubator.NativeHelper.optionalDowncallHandle;
 
 public class quiche_h
 {
@@ -166,6 +167,38 @@ public class quiche_h
         FunctionDescriptor.ofVoid(C_POINTER, C_LONG)
     );
 
+    private static final MethodHandle quiche_config_enable_early_data$MH = downcallHandle(
+        "quiche_config_enable_early_data",
+        "(Ljdk/incubator/foreign/MemoryAddress;)V",
+        FunctionDescriptor.ofVoid(C_POINTER)
+    );
+
+    private static final MethodHandle quiche_config_log_keys$MH = downcallHandle(
+        "quiche_config_log_keys",
+        "(Ljdk/incubator/foreign/MemoryAddress;)V",
+        FunctionDescriptor.ofVoid(C_POINTER)
+    );
+
+    private static final MethodHandle quiche_config_enable_dgram$MH = downcallHandle(
+        "quiche_config_enable_dgram",
+        "(Ljdk/incubator/foreign/MemoryAddress;BJJ)V",
+        FunctionDescriptor.ofVoid(C_POINTER, C_CHAR, C_LONG, C_LONG)
+    );
+
+    // Not available in all versions of the native library.
+    private static final MethodHandle quiche_config_set_initial_congestion_window_packets$MH = optionalDowncallHandle(
+        "quiche_config_set_initial_congestion_window_packets",
+        "(Ljdk/incubator/foreign/MemoryAddress;J)V",
+        FunctionDescriptor.ofVoid(C_POINTER, C_LONG)
+    );
+
+    // Not available in all versions of the native library.
+    private static final MethodHandle quiche_config_set_ticket_key$MH = optionalDowncallHandle(
+        "quiche_config_set_ticket_key",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;J)I",
+        FunctionDescriptor.of(C_INT, C_POINTER, C_POINTER, C_LONG)
+    );
+
     private static final MethodHandle quiche_config_free$MH = downcallHandle(
         "quiche_config_free",
         "(Ljdk/incubator/foreign/MemoryAddress;)V",
@@ -226,6 +259,30 @@ public class quiche_h
         FunctionDescriptor.of(C_CHAR, C_POINTER)
     );
 
+    private static final MethodHandle quiche_conn_is_in_early_data$MH = downcallHandle(
+        "quiche_conn_is_in_early_data",
+        "(Ljdk/incubator/foreign/MemoryAddress;)B",
+        FunctionDescriptor.of(C_CHAR, C_POINTER)
+    );
+
+    private static final MethodHandle quiche_conn_set_session$MH = downcallHandle(
+        "quiche_conn_set_session",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;J)I",
+        FunctionDescriptor.of(C_INT, C_POINTER, C_POINTER, C_LONG)
+    );
+
+    private static final MethodHandle quiche_conn_session$MH = downcallHandle(
+        "quiche_conn_session",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;)V",
+        FunctionDescriptor.ofVoid(C_POINTER, C_POINTER, C_POINTER)
+    );
+
+    private static final MethodHandle quiche_conn_is_resumed$MH = downcallHandle(
+        "quiche_conn_is_resumed",
+        "(Ljdk/incubator/foreign/MemoryAddress;)B",
+        FunctionDescriptor.of(C_CHAR, C_POINTER)
+    );
+
     private static final MethodHandle quiche_conn_application_proto$MH = downcallHandle(
         "quiche_conn_application_proto",
         "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;)V",
@@ -256,6 +313,12 @@ public class quiche_h
         FunctionDescriptor.of(C_CHAR, C_POINTER)
     );
 
+    private static final MethodHandle quiche_conn_set_keylog_path$MH = downcallHandle(
+        "quiche_conn_set_keylog_path",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;)B",
+        FunctionDescriptor.of(C_CHAR, C_POINTER, C_POINTER)
+    );
+
     private static final MethodHandle quiche_conn_peer_cert$MH = downcallHandle(
         "quiche_conn_peer_cert",
         "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;)V",
@@ -316,6 +379,30 @@ public class quiche_h
         FunctionDescriptor.of(C_LONG, C_POINTER, C_LONG, C_POINTER, C_LONG, C_POINTER)
     );
 
+    private static final MethodHandle quiche_conn_dgram_max_writable_len$MH = downcallHandle(
+        "quiche_conn_dgram_max_writable_len",
+        "(Ljdk/incubator/foreign/MemoryAddress;)J",
+        FunctionDescriptor.of(C_LONG, C_POINTER)
+    );
+
+    private static final MethodHandle quiche_conn_dgram_recv_front_len$MH = downcallHandle(
+        "quiche_conn_dgram_recv_front_len",
+        "(Ljdk/incubator/foreign/MemoryAddress;)J",
+        FunctionDescriptor.of(C_LONG, C_POINTER)
+    );
+
+    private static final MethodHandle quiche_conn_dgram_recv$MH = downcallHandle(
+        "quiche_conn_dgram_recv",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;J)J",
+        FunctionDescriptor.of(C_LONG, C_POINTER, C_POINTER, C_LONG)
+    );
+
+    private static final MethodHandle quiche_conn_dgram_send$MH = downcallHandle(
+        "quiche_conn_dgram_send",
+        "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;J)J",
+        FunctionDescriptor.of(C_LONG, C_POINTER, C_POINTER, C_LONG)
+    );
+
     private static final MethodHandle quiche_stream_iter_next$MH = downcallHandle(
         "quiche_stream_iter_next",
         "(Ljdk/incubator/foreign/MemoryAddress;Ljdk/incubator/foreign/MemoryAddress;)B",
@@ -466,6 +553,80 @@ public class quiche_h
         }
     }
 
+    public static void quiche_config_enable_early_data(MemoryAddress config)
+    {
+        try
+        {
+            quiche_config_enable_early_data$MH.invokeExact(config);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static void quiche_config_log_keys(MemoryAddress config)
+    {
+        try
+        {
+            quiche_config_log_keys$MH.invokeExact(config);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static void quiche_config_enable_dgram(MemoryAddress config, byte enabled, long recv_queue_len, long send_queue_len)
+    {
+        try
+        {
+            quiche_config_enable_dgram$MH.invokeExact(config, enabled, recv_queue_len, send_queue_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static boolean quiche_config_has_initial_congestion_window_packets()
+    {
+        return quiche_config_set_initial_congestion_window_packets$MH != null;
+    }
+
+    public static void quiche_config_set_initial_congestion_window_packets(MemoryAddress config, long packets)
+    {
+        if (quiche_config_set_initial_congestion_window_packets$MH == null)
+            throw new UnsatisfiedLinkError("unresolved symbol: quiche_config_set_initial_congestion_window_packets");
+        try
+        {
+            quiche_config_set_initial_congestion_window_packets$MH.invokeExact(config, packets);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static boolean quiche_config_has_ticket_key()
+    {
+        return quiche_config_set_ticket_key$MH != null;
+    }
+
+    public static int quiche_config_set_ticket_key(MemoryAddress config, MemoryAddress key, long key_len)
+    {
+        if (quiche_config_set_ticket_key$MH == null)
+            throw new UnsatisfiedLinkError("unresolved symbol: quiche_config_set_ticket_key");
+        try
+        {
+            return (int)quiche_config_set_ticket_key$MH.invokeExact(config, key, key_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
     public static void quiche_config_set_max_connection_window(MemoryAddress config, long v)
     {
         try
@@ -670,6 +831,54 @@ public class quiche_h
         }
     }
 
+    public static long quiche_conn_dgram_max_writable_len(MemoryAddress conn)
+    {
+        try
+        {
+            return (long) quiche_conn_dgram_max_writable_len$MH.invokeExact(conn);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static long quiche_conn_dgram_recv_front_len(MemoryAddress conn)
+    {
+        try
+        {
+            return (long) quiche_conn_dgram_recv_front_len$MH.invokeExact(conn);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static long quiche_conn_dgram_recv(MemoryAddress conn, MemoryAddress buf, long buf_len)
+    {
+        try
+        {
+            return (long) quiche_conn_dgram_recv$MH.invokeExact(conn, buf, buf_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static long quiche_conn_dgram_send(MemoryAddress conn, MemoryAddress buf, long buf_len)
+    {
+        try
+        {
+            return (long) quiche_conn_dgram_send$MH.invokeExact(conn, buf, buf_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
     public static byte quiche_stream_iter_next(MemoryAddress conn, MemoryAddress stream_id)
     {
         try
@@ -718,6 +927,18 @@ public class quiche_h
         }
     }
 
+    public static byte quiche_conn_set_keylog_path(MemoryAddress conn, MemoryAddress path)
+    {
+        try
+        {
+            return (byte)quiche_conn_set_keylog_path$MH.invokeExact(conn, path);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
     public static void quiche_conn_peer_cert(MemoryAddress conn, MemoryAddress out, MemoryAddress out_len)
     {
         try
@@ -886,6 +1107,54 @@ public class quiche_h
         }
     }
 
+    public static byte quiche_conn_is_in_early_data(MemoryAddress conn)
+    {
+        try
+        {
+            return (byte)quiche_conn_is_in_early_data$MH.invokeExact(conn);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static int quiche_conn_set_session(MemoryAddress conn, MemoryAddress buf, long buf_len)
+    {
+        try
+        {
+            return (int)quiche_conn_set_session$MH.invokeExact(conn, buf, buf_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static byte quiche_conn_is_resumed(MemoryAddress conn)
+    {
+        try
+        {
+            return (byte)quiche_conn_is_resumed$MH.invokeExact(conn);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
+    public static void quiche_conn_session(MemoryAddress conn, MemoryAddress out, MemoryAddress out_len)
+    {
+        try
+        {
+            quiche_conn_session$MH.invokeExact(conn, out, out_len);
+        }
+        catch (Throwable ex)
+        {
+            throw new AssertionError("should not reach here", ex);
+        }
+    }
+
     public static byte quiche_conn_is_established(MemoryAddress conn)
     {
         try
@@ -65,6 +65,21 @@ public class quiche_path_stats
         return (long)cwnd.get(stats);
     }
 
+    private static final long peer_addr_offset = LAYOUT.byteOffset(MemoryLayout.PathElement.groupElement("peer_addr"));
+    private static final VarHandle peer_addr_len = LAYOUT.varHandle(int.class, MemoryLayout.PathElement.groupElement("peer_addr_len"));
+    private static final VarHandle active = LAYOUT.varHandle(byte.class, MemoryLayout.PathElement.groupElement("active"));
+
+    public static byte[] get_peer_addr(MemorySegment stats)
+    {
+        int length = (int)peer_addr_len.get(stats);
+        return stats.asSlice(peer_addr_offset, length).toByteArray();
+    }
+
+    public static boolean get_active(MemorySegment stats)
+    {
+        return (byte)active.get(stats) != 0;
+    }
+
     public static MemorySegment allocate(ResourceScope scope)
     {
         return MemorySegment.allocateNative(LAYOUT, scope);
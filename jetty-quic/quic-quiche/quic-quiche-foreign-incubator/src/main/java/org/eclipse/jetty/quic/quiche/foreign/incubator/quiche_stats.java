@@ -61,4 +61,11 @@ public class quiche_stats
     {
         return (long)peer_initial_max_streams_bidi.get(stats);
     }
+
+    private static final VarHandle paths_count = LAYOUT.varHandle(long.class, MemoryLayout.PathElement.groupElement("paths_count"));
+
+    public static long get_paths_count(MemorySegment stats)
+    {
+        return (long)paths_count.get(stats);
+    }
 }
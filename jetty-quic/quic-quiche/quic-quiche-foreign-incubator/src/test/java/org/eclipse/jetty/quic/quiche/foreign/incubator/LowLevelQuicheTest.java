@@ -225,6 +225,43 @@ public class LowLevelQuicheTest
         assertThat(Arrays.equals(serverCert, peerCertificate), is(true));
     }
 
+    @Test
+    public void testUndecryptablePacketDoesNotChangeActivePeerAddress() throws Exception
+    {
+        // establish connection
+        Map.Entry<ForeignIncubatorQuicheConnection, ForeignIncubatorQuicheConnection> entry = connectClientToServer();
+        ForeignIncubatorQuicheConnection clientQuicheConnection = entry.getKey();
+        ForeignIncubatorQuicheConnection serverQuicheConnection = entry.getValue();
+
+        InetSocketAddress spoofedSocketAddress = new InetSocketAddress("localhost", 7777);
+        assertThat(serverQuicheConnection.isActivePeerAddress(clientSocketAddress), is(true));
+        assertThat(serverQuicheConnection.isActivePeerAddress(spoofedSocketAddress), is(false));
+
+        // client sends 16 bytes of payload over stream 0
+        assertThat(clientQuicheConnection.feedClearBytesForStream(0, ByteBuffer.allocate(16)
+            .putInt(0xdeadbeef)
+            .putInt(0xcafebabe)
+            .putInt(0xdeadc0de)
+            .putInt(0xbaddecaf)
+            .flip()), is(16));
+        ByteBuffer buffer = ByteBuffer.allocate(QUICHE_MIN_CLIENT_INITIAL_LEN);
+        int drained = clientQuicheConnection.drainCipherBytes(buffer);
+        buffer.flip();
+
+        // corrupt the authentication tag and feed the packet from another address
+        int last = buffer.limit() - 1;
+        buffer.put(last, (byte)~buffer.get(last));
+        int fed = serverQuicheConnection.feedCipherBytes(buffer, serverSocketAddress, spoofedSocketAddress);
+
+        // quiche reports the packet as consumed, but drops it
+        assertThat(fed, is(drained));
+        assertThat(serverQuicheConnection.readableStreamIds().size(), is(0));
+
+        // the active path has not changed
+        assertThat(serverQuicheConnection.isActivePeerAddress(clientSocketAddress), is(true));
+        assertThat(serverQuicheConnection.isActivePeerAddress(spoofedSocketAddress), is(false));
+    }
+
     private void drainServerToFeedClient(Map.Entry<ForeignIncubatorQuicheConnection, ForeignIncubatorQuicheConnection> entry, int expectedSize) throws IOException
     {
         ForeignIncubatorQuicheConnection clientQuicheConnection = entry.getKey();
@@ -63,9 +63,9 @@ public class JnaQuicheBinding implements QuicheBinding
     }
 
     @Override
-    public boolean negotiate(QuicheConnection.TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
+    public boolean negotiate(QuicheConnection.TokenMinter tokenMinter, QuicheConnection.ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
     {
-        return JnaQuicheConnection.negotiate(tokenMinter, packetRead, packetToSend);
+        return JnaQuicheConnection.negotiate(tokenMinter, connectionIdGenerator, packetRead, packetToSend);
     }
 
     @Override
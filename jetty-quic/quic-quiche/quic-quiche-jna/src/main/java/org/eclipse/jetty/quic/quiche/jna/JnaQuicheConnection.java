@@ -20,6 +20,7 @@ import java.net.SocketAddress;
 import java.nio.ByteBuffer;
 import java.security.SecureRandom;
 import java.util.ArrayList;
+import java.util.Arrays;
 import java.util.List;
 
 import org.eclipse.jetty.quic.quiche.Quiche;
@@ -117,7 +118,10 @@ public class JnaQuicheConnection extends QuicheConnection
         SizedStructure<sockaddr> localSockaddr = sockaddr.convert(local);
         SizedStructure<sockaddr> peerSockaddr = sockaddr.convert(peer);
         LibQuiche.quiche_conn quicheConn = LibQuiche.INSTANCE.quiche_connect(peer.getHostString(), scid, new size_t(scid.length), localSockaddr.getStructure(), localSockaddr.getSize(), peerSockaddr.getStructure(), peerSockaddr.getSize(), libQuicheConfig);
-        return new JnaQuicheConnection(quicheConn, libQuicheConfig);
+        JnaQuicheConnection connection = new JnaQuicheConnection(quicheConn, libQuicheConfig);
+        connection.setSourceConnectionId(scid);
+        connection.enableKeyLog(quicheConfig.getKeyLogPath());
+        return connection;
     }
 
     private static LibQuiche.quiche_config buildConfig(QuicheConfig config) throws IOException
@@ -216,6 +220,51 @@ public class JnaQuicheConnection extends QuicheConnection
         if (activeConnectionIdLimit != null)
             LibQuiche.INSTANCE.quiche_config_set_active_connection_id_limit(quicheConfig, new uint64_t(activeConnectionIdLimit));
 
+        Boolean enableEarlyData = config.getEnableEarlyData();
+        if (enableEarlyData != null && enableEarlyData)
+            LibQuiche.INSTANCE.quiche_config_enable_early_data(quicheConfig);
+
+        Boolean enableDgram = config.getEnableDgram();
+        if (enableDgram != null)
+        {
+            Long recvQueueLen = config.getDgramRecvQueueLen();
+            Long sendQueueLen = config.getDgramSendQueueLen();
+            LibQuiche.INSTANCE.quiche_config_enable_dgram(quicheConfig, enableDgram,
+                new size_t(recvQueueLen == null ? QuicheConfig.DEFAULT_DGRAM_QUEUE_LEN : recvQueueLen),
+                new size_t(sendQueueLen == null ? QuicheConfig.DEFAULT_DGRAM_QUEUE_LEN : sendQueueLen));
+        }
+
+        Long initialCongestionWindowPackets = config.getInitialCongestionWindowPackets();
+        if (initialCongestionWindowPackets != null)
+        {
+            try
+            {
+                LibQuiche.INSTANCE.quiche_config_set_initial_congestion_window_packets(quicheConfig, new size_t(initialCongestionWindowPackets));
+            }
+            catch (UnsatisfiedLinkError x)
+            {
+                LOG.warn("initial congestion window not supported by native quiche library {}, ignoring", LibQuiche.INSTANCE.quiche_version());
+            }
+        }
+
+        if (config.getKeyLogPath() != null)
+            LibQuiche.INSTANCE.quiche_config_log_keys(quicheConfig);
+
+        byte[] ticketKey = config.getTicketKey();
+        if (ticketKey != null)
+        {
+            try
+            {
+                int rc = LibQuiche.INSTANCE.quiche_config_set_ticket_key(quicheConfig, ticketKey, new size_t(ticketKey.length));
+                if (rc < 0)
+                    throw new IOException("Error setting ticket key : " + Quiche.quiche_error.errToString(rc));
+            }
+            catch (UnsatisfiedLinkError x)
+            {
+                LOG.warn("ticket key not supported by native quiche library {}, ignoring", LibQuiche.INSTANCE.quiche_version());
+            }
+        }
+
         return quicheConfig;
     }
 
@@ -257,6 +306,16 @@ public class JnaQuicheConnection extends QuicheConnection
      * and the {@code packetRead} buffer can be dropped.
      */
     public static boolean negotiate(TokenMinter tokenMinter, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
+    {
+        return negotiate(tokenMinter, null, packetRead, packetToSend);
+    }
+
+    /**
+     * Fully consumes the {@code packetRead} buffer.
+     * @return true if a negotiation packet was written to the {@code packetToSend} buffer, false if negotiation failed
+     * and the {@code packetRead} buffer can be dropped.
+     */
+    public static boolean negotiate(TokenMinter tokenMinter, ConnectionIdGenerator connectionIdGenerator, ByteBuffer packetRead, ByteBuffer packetToSend) throws IOException
     {
         uint8_t_pointer type = new uint8_t_pointer();
         uint32_t_pointer version = new uint32_t_pointer();
@@ -305,8 +364,7 @@ public class JnaQuicheConnection extends QuicheConnection
 
             token = tokenMinter.mint(dcid, (int)dcid_len.getValue());
 
-            byte[] newCid = new byte[QUICHE_MAX_CONN_ID_LEN];
-            SECURE_RANDOM.nextBytes(newCid);
+            byte[] newCid = newConnectionId(connectionIdGenerator);
 
             ssize_t generated = LibQuiche.INSTANCE.quiche_retry(scid, scid_len.getPointee(),
                 dcid, dcid_len.getPointee(),
@@ -328,6 +386,20 @@ public class JnaQuicheConnection extends QuicheConnection
      * Fully consumes the {@code packetRead} buffer if the connection was accepted.
      * @return an established connection if accept succeeded, null if accept failed and negotiation should be tried.
      */
+    private static byte[] newConnectionId(ConnectionIdGenerator connectionIdGenerator)
+    {
+        if (connectionIdGenerator != null)
+        {
+            byte[] cid = connectionIdGenerator.generate();
+            if (cid.length != QUICHE_MAX_CONN_ID_LEN)
+                throw new IllegalStateException("invalid connection ID length " + cid.length + " from " + connectionIdGenerator);
+            return cid;
+        }
+        byte[] cid = new byte[QUICHE_MAX_CONN_ID_LEN];
+        SECURE_RANDOM.nextBytes(cid);
+        return cid;
+    }
+
     public static JnaQuicheConnection tryAccept(QuicheConfig quicheConfig, TokenValidator tokenValidator, ByteBuffer packetRead, SocketAddress local, SocketAddress peer) throws IOException
     {
         uint8_t_pointer type = new uint8_t_pointer();
@@ -365,25 +437,32 @@ public class JnaQuicheConnection extends QuicheConnection
             return null;
         }
 
+        // Original Destination Connection ID
+        byte[] odcid = null;
         if (token_len.getValue() == 0)
         {
-            LOG.debug("need stateless retry");
-            return null;
+            if (tokenValidator.isRetryRequired())
+            {
+                LOG.debug("need stateless retry");
+                return null;
+            }
+            LOG.debug("accepting without address validation");
+        }
+        else
+        {
+            LOG.debug("token validation...");
+            odcid = tokenValidator.validate(token, (int)token_len.getValue());
+            if (odcid == null)
+                throw new TokenValidationException("invalid address validation token");
+            LOG.debug("validated token");
         }
-
-        LOG.debug("token validation...");
-        // Original Destination Connection ID
-        byte[] odcid = tokenValidator.validate(token, (int)token_len.getValue());
-        if (odcid == null)
-            throw new TokenValidationException("invalid address validation token");
-        LOG.debug("validated token");
 
         LOG.debug("connection creation...");
         LibQuiche.quiche_config libQuicheConfig = buildConfig(quicheConfig);
 
         SizedStructure<sockaddr> localSockaddr = sockaddr.convert(local);
         SizedStructure<sockaddr> peerSockaddr = sockaddr.convert(peer);
-        LibQuiche.quiche_conn quicheConn = LibQuiche.INSTANCE.quiche_accept(dcid, dcid_len.getPointee(), odcid, new size_t(odcid.length), localSockaddr.getStructure(), localSockaddr.getSize(), peerSockaddr.getStructure(), peerSockaddr.getSize(), libQuicheConfig);
+        LibQuiche.quiche_conn quicheConn = LibQuiche.INSTANCE.quiche_accept(dcid, dcid_len.getPointee(), odcid, new size_t(odcid == null ? 0 : odcid.length), localSockaddr.getStructure(), localSockaddr.getSize(), peerSockaddr.getStructure(), peerSockaddr.getSize(), libQuicheConfig);
 
         if (quicheConn == null)
         {
@@ -393,6 +472,7 @@ public class JnaQuicheConnection extends QuicheConnection
 
         LOG.debug("connection created");
         JnaQuicheConnection quicheConnection = new JnaQuicheConnection(quicheConn, libQuicheConfig);
+        quicheConnection.enableKeyLog(quicheConfig.getKeyLogPath());
         LOG.debug("accepted, immediately receiving the same packet - remaining in buffer: {}", packetRead.remaining());
         while (packetRead.hasRemaining())
         {
@@ -413,6 +493,17 @@ public class JnaQuicheConnection extends QuicheConnection
         }
     }
 
+    private void enableKeyLog(String keyLogPath)
+    {
+        if (keyLogPath == null)
+            return;
+        try (AutoLock ignore = lock.lock())
+        {
+            if (!LibQuiche.INSTANCE.quiche_conn_set_keylog_path(quicheConn, keyLogPath))
+                LOG.warn("Unable to set TLS key log path to {}", keyLogPath);
+        }
+    }
+
     public byte[] getPeerCertificate()
     {
         try (AutoLock ignore = lock.lock())
@@ -479,6 +570,33 @@ public class JnaQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public boolean isActivePeerAddress(SocketAddress peer)
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            SizedStructure<sockaddr> peerSockaddr = sockaddr.convert(peer);
+            byte[] peerBytes = peerSockaddr.getStructure().getPointer().getByteArray(0, peerSockaddr.getSize().intValue());
+            LibQuiche.quiche_stats stats = new LibQuiche.quiche_stats();
+            LibQuiche.INSTANCE.quiche_conn_stats(quicheConn, stats);
+            long pathsCount = stats.paths_count.longValue();
+            for (long i = 0; i < pathsCount; ++i)
+            {
+                LibQuiche.quiche_path_stats pathStats = new LibQuiche.quiche_path_stats();
+                if (LibQuiche.INSTANCE.quiche_conn_path_stats(quicheConn, new size_t(i), pathStats) != 0)
+                    continue;
+                if (!pathStats.active)
+                    continue;
+                if (pathStats.peer_addr_len.intValue() != peerBytes.length)
+                    return false;
+                return Arrays.equals(peerBytes, Arrays.copyOf(pathStats.peer_addr.opaque, peerBytes.length));
+            }
+            return false;
+        }
+    }
+
     /**
      * Fill the given buffer with cipher text to be sent.
      * @param buffer the buffer to fill.
@@ -531,6 +649,7 @@ public class JnaQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
     public boolean isConnectionInEarlyData()
     {
         try (AutoLock ignore = lock.lock())
@@ -541,6 +660,52 @@ public class JnaQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public byte[] getSession()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            char_pointer out = new char_pointer();
+            size_t_pointer outLen = new size_t_pointer();
+            LibQuiche.INSTANCE.quiche_conn_session(quicheConn, out, outLen);
+            int length = (int)outLen.getValue();
+            if (length == 0)
+                return null;
+            return out.getValueAsBytes(length);
+        }
+    }
+
+    @Override
+    public boolean setSession(byte[] session)
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            int rc = LibQuiche.INSTANCE.quiche_conn_set_session(quicheConn, session, new size_t(session.length));
+            if (rc < 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("could not set session: {}", quiche_error.errToString(rc));
+                return false;
+            }
+            return true;
+        }
+    }
+
+    @Override
+    public boolean isResumed()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            return LibQuiche.INSTANCE.quiche_conn_is_resumed(quicheConn);
+        }
+    }
+
     @Override
     public long nextTimeout()
     {
@@ -729,6 +894,64 @@ public class JnaQuicheConnection extends QuicheConnection
         }
     }
 
+    @Override
+    public int maxDatagramLength()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            int length = LibQuiche.INSTANCE.quiche_conn_dgram_max_writable_len(quicheConn).intValue();
+            return length < 0 ? -1 : length;
+        }
+    }
+
+    @Override
+    public int nextDatagramLength()
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IllegalStateException("connection was released");
+            int length = LibQuiche.INSTANCE.quiche_conn_dgram_recv_front_len(quicheConn).intValue();
+            return length < 0 ? -1 : length;
+        }
+    }
+
+    @Override
+    public int feedClearBytesForDatagram(ByteBuffer buffer) throws IOException
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IOException("connection was released");
+            int written = LibQuiche.INSTANCE.quiche_conn_dgram_send(quicheConn, buffer, new size_t(buffer.remaining())).intValue();
+            if (written == quiche_error.QUICHE_ERR_DONE)
+                return 0;
+            if (written < 0L)
+                throw new IOException("failed to send datagram; quiche_err=" + quiche_error.errToString(written));
+            buffer.position(buffer.position() + written);
+            return written;
+        }
+    }
+
+    @Override
+    public int drainClearBytesForDatagram(ByteBuffer buffer) throws IOException
+    {
+        try (AutoLock ignore = lock.lock())
+        {
+            if (quicheConn == null)
+                throw new IOException("connection was released");
+            int read = LibQuiche.INSTANCE.quiche_conn_dgram_recv(quicheConn, buffer, new size_t(buffer.remaining())).intValue();
+            if (read == quiche_error.QUICHE_ERR_DONE)
+                return -1;
+            if (read < 0L)
+                throw new IOException("failed to receive datagram; quiche_err=" + quiche_error.errToString(read));
+            buffer.position(buffer.position() + read);
+            return read;
+        }
+    }
+
     @Override
     public CloseInfo getRemoteCloseInfo()
     {
@@ -134,6 +134,22 @@ public interface LibQuiche extends Library
     // Sets the limit of active connection IDs.
     void quiche_config_set_active_connection_id_limit(quiche_config config, uint64_t v);
 
+    // Enables sending or receiving early data.
+    void quiche_config_enable_early_data(quiche_config config);
+
+    // Configures the session ticket key material.
+    int quiche_config_set_ticket_key(quiche_config config, byte[] key, size_t key_len);
+
+    // Enables logging of secrets.
+    void quiche_config_log_keys(quiche_config config);
+
+    // Configures whether to enable receiving DATAGRAM frames.
+    void quiche_config_enable_dgram(quiche_config config, boolean enabled, size_t recv_queue_len, size_t send_queue_len);
+
+    // Sets the initial congestion window size in terms of packet count.
+    // Not available in all versions of the native library.
+    void quiche_config_set_initial_congestion_window_packets(quiche_config config, size_t packets);
+
     // Sets the initial stateless reset token. |v| must contain 16 bytes, otherwise the behaviour is undefined.
     void quiche_config_set_stateless_reset_token(quiche_config config, byte[] v);
 
@@ -340,6 +356,9 @@ public interface LibQuiche extends Library
     boolean quiche_conn_set_qlog_path(quiche_conn conn, String path,
                                       String log_title, String log_desc);
 
+    // Enables keylog to the specified file path. Returns true on success.
+    boolean quiche_conn_set_keylog_path(quiche_conn conn, String path);
+
     // Writes a version negotiation packet.
     ssize_t quiche_negotiate_version(byte[] scid, size_t scid_len,
                                      byte[] dcid, size_t dcid_len,
@@ -415,6 +434,15 @@ public interface LibQuiche extends Library
     // Returns true if the connection handshake is complete.
     boolean quiche_conn_is_established(quiche_conn conn);
 
+    // Configures the given session for resumption.
+    int quiche_conn_set_session(quiche_conn conn, byte[] buf, size_t buf_len);
+
+    // Returns the serialized cryptographic session for the connection.
+    void quiche_conn_session(quiche_conn conn, char_pointer out, size_t_pointer out_len);
+
+    // Returns true if the connection is resumed.
+    boolean quiche_conn_is_resumed(quiche_conn conn);
+
     // Returns true if the connection has a pending handshake that has progressed
     // enough to send or receive early data.
     boolean quiche_conn_is_in_early_data(quiche_conn conn);
@@ -495,6 +523,18 @@ public interface LibQuiche extends Library
     // Writes data to a stream.
     ssize_t quiche_conn_stream_send(quiche_conn conn, uint64_t stream_id, ByteBuffer buf, size_t buf_len, boolean fin);
 
+    // Returns the maximum DATAGRAM payload that can be sent.
+    ssize_t quiche_conn_dgram_max_writable_len(quiche_conn conn);
+
+    // Returns the length of the first stored DATAGRAM.
+    ssize_t quiche_conn_dgram_recv_front_len(quiche_conn conn);
+
+    // Reads the first received DATAGRAM.
+    ssize_t quiche_conn_dgram_recv(quiche_conn conn, ByteBuffer buf, size_t buf_len);
+
+    // Sends data in a DATAGRAM frame.
+    ssize_t quiche_conn_dgram_send(quiche_conn conn, ByteBuffer buf, size_t buf_len);
+
     // Frees the connection object.
     void quiche_conn_free(quiche_conn conn);
 }
@@ -0,0 +1,93 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+
+/**
+ * <p>Tracks the address validation of the clients of a {@link QuicServerConnector}.</p>
+ *
+ * @see RetryPolicy
+ */
+@ManagedObject("Tracks QUIC address validation")
+public class AddressValidationStatistics
+{
+    private final LongAdder _issued = new LongAdder();
+    private final LongAdder _validated = new LongAdder();
+    private final LongAdder _rejected = new LongAdder();
+    private final LongAdder _unvalidated = new LongAdder();
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void reset()
+    {
+        _issued.reset();
+        _validated.reset();
+        _rejected.reset();
+        _unvalidated.reset();
+    }
+
+    void onTokenIssued()
+    {
+        _issued.increment();
+    }
+
+    void onTokenValidated()
+    {
+        _validated.increment();
+    }
+
+    void onTokenRejected()
+    {
+        _rejected.increment();
+    }
+
+    void onAcceptedWithoutValidation()
+    {
+        _unvalidated.increment();
+    }
+
+    @ManagedAttribute("The number of address validation tokens issued in Retry packets")
+    public long getTokensIssued()
+    {
+        return _issued.sum();
+    }
+
+    @ManagedAttribute("The number of valid address validation tokens received")
+    public long getTokensValidated()
+    {
+        return _validated.sum();
+    }
+
+    @ManagedAttribute("The number of invalid or expired address validation tokens received")
+    public long getTokensRejected()
+    {
+        return _rejected.sum();
+    }
+
+    @ManagedAttribute("The number of connections accepted without address validation")
+    public long getAcceptedWithoutValidation()
+    {
+        return _unvalidated.sum();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{issued=%d,validated=%d,rejected=%d,unvalidated=%d}", getClass().getSimpleName(), hashCode(), getTokensIssued(), getTokensValidated(), getTokensRejected(), getAcceptedWithoutValidation());
+    }
+}
@@ -0,0 +1,167 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.net.InetSocketAddress;
+import java.nio.ByteBuffer;
+import java.security.GeneralSecurityException;
+import java.security.MessageDigest;
+import java.util.Arrays;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+import javax.crypto.Mac;
+import javax.crypto.spec.SecretKeySpec;
+
+import org.eclipse.jetty.io.ssl.ResumptionKeys;
+import org.eclipse.jetty.io.ssl.ResumptionStore;
+import org.eclipse.jetty.quic.quiche.QuicheConnection;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Mints and validates the QUIC address validation tokens sent in Retry packets.</p>
+ * <p>Tokens are authenticated with keys shared via a {@link ResumptionStore},
+ * so that a token minted by any node of a cluster is valid on any other node,
+ * for example when a load balancer routes the retried Initial packet to a
+ * different node; tokens expire after the {@link #getTokenLifetime() token lifetime}.</p>
+ * <p>The token format is:</p>
+ * <pre>
+ * version (1) | timestamp (4) | original destination connection ID (0..20) | MAC (16)
+ * </pre>
+ * <p>where the MAC is a truncated HMAC-SHA256 of the client address and port,
+ * the version, the timestamp and the original destination connection ID.</p>
+ */
+public class AddressValidationTokens
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AddressValidationTokens.class);
+    private static final byte VERSION = 1;
+    private static final int HEADER_LENGTH = 1 + Integer.BYTES;
+    private static final int MAC_LENGTH = 16;
+    private static final String ALGORITHM = "HmacSHA256";
+
+    private final ResumptionKeys keys;
+    private long tokenLifetime = 10000;
+
+    /**
+     * @param keys the keys used to authenticate the tokens
+     */
+    public AddressValidationTokens(ResumptionKeys keys)
+    {
+        this.keys = Objects.requireNonNull(keys);
+    }
+
+    public ResumptionKeys getResumptionKeys()
+    {
+        return keys;
+    }
+
+    /**
+     * @return the time, in milliseconds, after which tokens are not valid anymore
+     */
+    public long getTokenLifetime()
+    {
+        return tokenLifetime;
+    }
+
+    /**
+     * @param tokenLifetime the time, in milliseconds, after which tokens are not valid anymore
+     */
+    public void setTokenLifetime(long tokenLifetime)
+    {
+        this.tokenLifetime = tokenLifetime;
+    }
+
+    /**
+     * @param address the address of the client the tokens are minted for
+     * @return a token minter for the given client
+     */
+    public QuicheConnection.TokenMinter newTokenMinter(InetSocketAddress address)
+    {
+        return (dcid, len) -> mint(address, Arrays.copyOf(dcid, len), System.currentTimeMillis());
+    }
+
+    /**
+     * @param address the address of the client the tokens are received from
+     * @return a token validator for the given client
+     */
+    public QuicheConnection.TokenValidator newTokenValidator(InetSocketAddress address)
+    {
+        return (token, len) -> validate(address, Arrays.copyOf(token, len), System.currentTimeMillis());
+    }
+
+    byte[] mint(InetSocketAddress address, byte[] odcid, long now)
+    {
+        ByteBuffer token = ByteBuffer.allocate(HEADER_LENGTH + odcid.length + MAC_LENGTH);
+        token.put(VERSION);
+        token.putInt((int)TimeUnit.MILLISECONDS.toSeconds(now));
+        token.put(odcid);
+        byte[] mac = mac(keys.getCurrentKey(), address, token.array(), token.position());
+        token.put(mac, 0, MAC_LENGTH);
+        return token.array();
+    }
+
+    byte[] validate(InetSocketAddress address, byte[] token, long now)
+    {
+        int length = token.length - MAC_LENGTH;
+        if (length < HEADER_LENGTH || token[0] != VERSION)
+            return null;
+
+        byte[] tokenMac = Arrays.copyOfRange(token, length, token.length);
+        boolean valid = false;
+        for (byte[] key : keys.getKeys())
+        {
+            byte[] mac = Arrays.copyOf(mac(key, address, token, length), MAC_LENGTH);
+            if (MessageDigest.isEqual(mac, tokenMac))
+            {
+                valid = true;
+                break;
+            }
+        }
+        if (!valid)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("invalid token from {}", address);
+            return null;
+        }
+
+        long timestamp = TimeUnit.SECONDS.toMillis(ByteBuffer.wrap(token, 1, Integer.BYTES).getInt() & 0xFFFF_FFFFL);
+        // Allow for the truncation of the timestamp to seconds.
+        if (now - timestamp > tokenLifetime + 1000)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("expired token from {}", address);
+            return null;
+        }
+
+        return Arrays.copyOfRange(token, HEADER_LENGTH, length);
+    }
+
+    private static byte[] mac(byte[] key, InetSocketAddress address, byte[] bytes, int length)
+    {
+        try
+        {
+            Mac mac = Mac.getInstance(ALGORITHM);
+            mac.init(new SecretKeySpec(key, ALGORITHM));
+            mac.update(address.getAddress().getAddress());
+            mac.update((byte)(address.getPort() >>> 8));
+            mac.update((byte)address.getPort());
+            mac.update(bytes, 0, length);
+            return mac.doFinal();
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new IllegalStateException(x);
+        }
+    }
+}
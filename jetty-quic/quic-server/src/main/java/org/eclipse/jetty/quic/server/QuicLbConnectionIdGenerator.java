@@ -0,0 +1,395 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.security.GeneralSecurityException;
+import java.security.SecureRandom;
+import java.util.Arrays;
+import java.util.Objects;
+import javax.crypto.Cipher;
+import javax.crypto.spec.SecretKeySpec;
+
+import org.eclipse.jetty.quic.quiche.Quiche;
+import org.eclipse.jetty.util.StringUtil;
+
+/**
+ * <p>Generates server connection IDs that embed a server ID, as specified by
+ * <a href="https://datatracker.ietf.org/doc/draft-ietf-quic-load-balancers/">QUIC-LB</a>,
+ * so that stateless load balancers can route all the packets of a QUIC connection
+ * to the same server, even when the client address changes.</p>
+ * <p>A generated connection ID is made of:</p>
+ * <ul>
+ *   <li>a first octet carrying the config rotation bits in its 3 most significant bits and,
+ *   if {@link #isLengthSelfEncoding() length self-encoding} is enabled, the connection ID length
+ *   minus one in its 5 least significant bits, otherwise 5 random bits</li>
+ *   <li>the server ID followed by a random nonce, transformed by the configured {@link Encryption}</li>
+ *   <li>random padding up to the connection ID length</li>
+ * </ul>
+ * <p>The load balancers must be configured with the same config rotation, server ID length,
+ * nonce length and key.</p>
+ *
+ * @see QuicServerConnector#setConnectionIdGenerator(QuicLbConnectionIdGenerator)
+ */
+public class QuicLbConnectionIdGenerator
+{
+    /**
+     * The length of the generated connection IDs.
+     */
+    public static final int CONNECTION_ID_LENGTH = Quiche.QUICHE_MAX_CONN_ID_LEN;
+
+    private final SecureRandom random = new SecureRandom();
+    private final int configRotation;
+    private final byte[] serverId;
+    private final int nonceLength;
+    private final Encryption encryption;
+    private boolean lengthSelfEncoding;
+
+    /**
+     * @param configRotation the config rotation codepoint, between 0 and 6
+     * @param serverId the server ID, between 1 and 15 bytes
+     * @param nonceLength the nonce length, between 4 and 18 bytes
+     * @param encryption the encryption of the server ID and nonce
+     */
+    public QuicLbConnectionIdGenerator(int configRotation, byte[] serverId, int nonceLength, Encryption encryption)
+    {
+        if (configRotation < 0 || configRotation > 6)
+            throw new IllegalArgumentException("Invalid config rotation " + configRotation);
+        if (serverId == null || serverId.length < 1 || serverId.length > 15)
+            throw new IllegalArgumentException("Invalid server ID length");
+        if (nonceLength < 4 || nonceLength > 18)
+            throw new IllegalArgumentException("Invalid nonce length " + nonceLength);
+        if (serverId.length + nonceLength > CONNECTION_ID_LENGTH - 1)
+            throw new IllegalArgumentException("Server ID and nonce longer than " + (CONNECTION_ID_LENGTH - 1) + " bytes");
+        this.configRotation = configRotation;
+        this.serverId = serverId.clone();
+        this.nonceLength = nonceLength;
+        this.encryption = Objects.requireNonNull(encryption);
+    }
+
+    /**
+     * <p>Creates a generator from string configuration, typically from XML files.</p>
+     *
+     * @param serverId the server ID as a hex string
+     * @param configRotation the config rotation codepoint
+     * @param nonceLength the nonce length
+     * @param key the 16 bytes AES key as a hex string, or blank for plaintext connection IDs
+     * @return a new generator, or null if the server ID is blank
+     */
+    public static QuicLbConnectionIdGenerator from(String serverId, int configRotation, int nonceLength, String key)
+    {
+        if (StringUtil.isBlank(serverId))
+            return null;
+        Encryption encryption = StringUtil.isBlank(key) ? Encryption.plaintext() : Encryption.aes(StringUtil.fromHexString(key.trim()));
+        return new QuicLbConnectionIdGenerator(configRotation, StringUtil.fromHexString(serverId.trim()), nonceLength, encryption);
+    }
+
+    public int getConfigRotation()
+    {
+        return configRotation;
+    }
+
+    public byte[] getServerId()
+    {
+        return serverId.clone();
+    }
+
+    public int getNonceLength()
+    {
+        return nonceLength;
+    }
+
+    public Encryption getEncryption()
+    {
+        return encryption;
+    }
+
+    /**
+     * @return whether the first octet of the connection IDs encodes their length
+     */
+    public boolean isLengthSelfEncoding()
+    {
+        return lengthSelfEncoding;
+    }
+
+    /**
+     * @param lengthSelfEncoding whether the first octet of the connection IDs encodes their length
+     */
+    public void setLengthSelfEncoding(boolean lengthSelfEncoding)
+    {
+        this.lengthSelfEncoding = lengthSelfEncoding;
+    }
+
+    /**
+     * @return a new connection ID of {@link #CONNECTION_ID_LENGTH} bytes
+     */
+    public byte[] generate()
+    {
+        byte[] cid = new byte[CONNECTION_ID_LENGTH];
+        random.nextBytes(cid);
+
+        int lowBits = lengthSelfEncoding ? CONNECTION_ID_LENGTH - 1 : cid[0] & 0x1F;
+        cid[0] = (byte)((configRotation << 5) | lowBits);
+
+        byte[] plaintext = Arrays.copyOfRange(cid, 1, 1 + serverId.length + nonceLength);
+        System.arraycopy(serverId, 0, plaintext, 0, serverId.length);
+        byte[] ciphertext = encryption.encrypt(plaintext);
+        System.arraycopy(ciphertext, 0, cid, 1, ciphertext.length);
+        return cid;
+    }
+
+    /**
+     * <p>Decodes the server ID from a connection ID generated with the same configuration.</p>
+     *
+     * @param cid the connection ID
+     * @return the server ID, or null if the connection ID is too short
+     * or does not have the configured config rotation
+     */
+    public byte[] decodeServerId(byte[] cid)
+    {
+        int length = serverId.length + nonceLength;
+        if (cid == null || cid.length < 1 + length)
+            return null;
+        if ((cid[0] & 0xFF) >>> 5 != configRotation)
+            return null;
+        byte[] plaintext = encryption.decrypt(Arrays.copyOfRange(cid, 1, 1 + length));
+        return Arrays.copyOf(plaintext, serverId.length);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[cr=%d,sid=%s,nonce=%d,%s]",
+            getClass().getSimpleName(),
+            hashCode(),
+            configRotation,
+            StringUtil.toHexString(serverId),
+            nonceLength,
+            encryption);
+    }
+
+    /**
+     * <p>The transformation applied to the server ID and nonce of connection IDs.</p>
+     * <p>Implementations must preserve the length of their input.</p>
+     */
+    public interface Encryption
+    {
+        byte[] encrypt(byte[] plaintext);
+
+        byte[] decrypt(byte[] ciphertext);
+
+        /**
+         * @return an encryption that leaves the server ID and nonce in clear,
+         * for deployments where the load balancer and the servers are in a trusted network
+         */
+        static Encryption plaintext()
+        {
+            return new Encryption()
+            {
+                @Override
+                public byte[] encrypt(byte[] plaintext)
+                {
+                    return plaintext.clone();
+                }
+
+                @Override
+                public byte[] decrypt(byte[] ciphertext)
+                {
+                    return ciphertext.clone();
+                }
+
+                @Override
+                public String toString()
+                {
+                    return "plaintext";
+                }
+            };
+        }
+
+        /**
+         * @param key the 16 bytes AES-128 key
+         * @return an encryption that uses AES-128-ECB directly when the server ID and nonce
+         * are 16 bytes long, and the four-pass algorithm otherwise
+         */
+        static Encryption aes(byte[] key)
+        {
+            return new AesEncryption(key);
+        }
+    }
+
+    private static class AesEncryption implements Encryption
+    {
+        private final SecretKeySpec key;
+
+        private AesEncryption(byte[] key)
+        {
+            if (key == null || key.length != 16)
+                throw new IllegalArgumentException("Invalid AES-128 key length");
+            this.key = new SecretKeySpec(key, "AES");
+        }
+
+        @Override
+        public byte[] encrypt(byte[] plaintext)
+        {
+            Cipher cipher = newCipher();
+            if (plaintext.length == 16)
+                return aes(cipher, plaintext);
+
+            int length = plaintext.length;
+            byte[] left = left(plaintext);
+            byte[] right = right(plaintext);
+            xorRight(right, aes(cipher, expand(left, length, 1)), length);
+            xorLeft(left, aes(cipher, expand(right, length, 2)), length);
+            xorRight(right, aes(cipher, expand(left, length, 3)), length);
+            xorLeft(left, aes(cipher, expand(right, length, 4)), length);
+            return join(left, right, length);
+        }
+
+        @Override
+        public byte[] decrypt(byte[] ciphertext)
+        {
+            if (ciphertext.length == 16)
+            {
+                try
+                {
+                    Cipher cipher = Cipher.getInstance("AES/ECB/NoPadding");
+                    cipher.init(Cipher.DECRYPT_MODE, key);
+                    return cipher.doFinal(ciphertext);
+                }
+                catch (GeneralSecurityException x)
+                {
+                    throw new IllegalStateException(x);
+                }
+            }
+
+            // The four-pass algorithm only uses the AES encryption function.
+            Cipher cipher = newCipher();
+            int length = ciphertext.length;
+            byte[] left = left(ciphertext);
+            byte[] right = right(ciphertext);
+            xorLeft(left, aes(cipher, expand(right, length, 4)), length);
+            xorRight(right, aes(cipher, expand(left, length, 3)), length);
+            xorLeft(left, aes(cipher, expand(right, length, 2)), length);
+            xorRight(right, aes(cipher, expand(left, length, 1)), length);
+            return join(left, right, length);
+        }
+
+        private Cipher newCipher()
+        {
+            try
+            {
+                Cipher cipher = Cipher.getInstance("AES/ECB/NoPadding");
+                cipher.init(Cipher.ENCRYPT_MODE, key);
+                return cipher;
+            }
+            catch (GeneralSecurityException x)
+            {
+                throw new IllegalStateException(x);
+            }
+        }
+
+        private static byte[] aes(Cipher cipher, byte[] block)
+        {
+            try
+            {
+                return cipher.doFinal(block);
+            }
+            catch (GeneralSecurityException x)
+            {
+                throw new IllegalStateException(x);
+            }
+        }
+
+        private static int halfLength(int length)
+        {
+            return (length + 1) / 2;
+        }
+
+        /**
+         * @return the first half of the input; for odd lengths, the low nibble of the middle byte is zeroed
+         */
+        private static byte[] left(byte[] input)
+        {
+            byte[] left = Arrays.copyOf(input, halfLength(input.length));
+            if (input.length % 2 != 0)
+                left[left.length - 1] &= 0xF0;
+            return left;
+        }
+
+        /**
+         * @return the second half of the input; for odd lengths, the high nibble of the middle byte is zeroed
+         */
+        private static byte[] right(byte[] input)
+        {
+            byte[] right = Arrays.copyOfRange(input, input.length - halfLength(input.length), input.length);
+            if (input.length % 2 != 0)
+                right[0] &= 0x0F;
+            return right;
+        }
+
+        private static byte[] join(byte[] left, byte[] right, int length)
+        {
+            byte[] result = new byte[length];
+            System.arraycopy(right, 0, result, length - right.length, right.length);
+            if (length % 2 != 0)
+            {
+                System.arraycopy(left, 0, result, 0, left.length - 1);
+                result[left.length - 1] = (byte)((left[left.length - 1] & 0xF0) | (right[0] & 0x0F));
+            }
+            else
+            {
+                System.arraycopy(left, 0, result, 0, left.length);
+            }
+            return result;
+        }
+
+        /**
+         * @return a 16 bytes block with the half, zero padding, the plaintext length and the pass index
+         */
+        private static byte[] expand(byte[] half, int length, int index)
+        {
+            byte[] block = new byte[16];
+            System.arraycopy(half, 0, block, 0, half.length);
+            block[14] = (byte)length;
+            block[15] = (byte)index;
+            return block;
+        }
+
+        private static void xorLeft(byte[] left, byte[] block, int length)
+        {
+            for (int i = 0; i < left.length; ++i)
+            {
+                left[i] ^= block[i];
+            }
+            if (length % 2 != 0)
+                left[left.length - 1] &= 0xF0;
+        }
+
+        private static void xorRight(byte[] right, byte[] block, int length)
+        {
+            int offset = block.length - right.length;
+            for (int i = 0; i < right.length; ++i)
+            {
+                right[i] ^= block[offset + i];
+            }
+            if (length % 2 != 0)
+                right[0] &= 0x0F;
+        }
+
+        @Override
+        public String toString()
+        {
+            return "aes";
+        }
+    }
+}
@@ -23,9 +23,11 @@ import java.nio.file.Path;
 import java.security.KeyStore;
 import java.util.EventListener;
 import java.util.List;
+import java.util.Objects;
 import java.util.Set;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.Executor;
+import java.util.concurrent.TimeUnit;
 
 import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.io.Connection;
@@ -33,16 +35,21 @@ import org.eclipse.jetty.io.DatagramChannelEndPoint;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.ManagedSelector;
 import org.eclipse.jetty.io.SelectorManager;
+import org.eclipse.jetty.io.ssl.ResumptionKeys;
+import org.eclipse.jetty.io.ssl.ResumptionStore;
+import org.eclipse.jetty.io.ssl.TlsResumptionStatistics;
 import org.eclipse.jetty.quic.common.QuicConfiguration;
 import org.eclipse.jetty.quic.common.QuicSession;
 import org.eclipse.jetty.quic.common.QuicSessionContainer;
 import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
 import org.eclipse.jetty.quic.quiche.PemExporter;
 import org.eclipse.jetty.quic.quiche.QuicheConfig;
+import org.eclipse.jetty.quic.quiche.QuicheConnection;
 import org.eclipse.jetty.server.AbstractNetworkConnector;
 import org.eclipse.jetty.server.ConnectionFactory;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
 import org.eclipse.jetty.util.thread.Scheduler;
 
@@ -59,6 +66,8 @@ public class QuicServerConnector extends AbstractNetworkConnector
 {
     private final QuicConfiguration quicConfiguration = new QuicConfiguration();
     private final QuicSessionContainer container = new QuicSessionContainer();
+    private final ResumptionListener resumptionListener = new ResumptionListener();
+    private final AddressValidationStatistics addressValidationStatistics = new AddressValidationStatistics();
     private final ServerDatagramSelectorManager selectorManager;
     private final SslContextFactory.Server sslContextFactory;
     private Path privateKeyPemPath;
@@ -70,6 +79,13 @@ public class QuicServerConnector extends AbstractNetworkConnector
     private int outputBufferSize = 2048;
     private boolean useInputDirectByteBuffers = true;
     private boolean useOutputDirectByteBuffers = true;
+    private QuicLbConnectionIdGenerator connectionIdGenerator;
+    private ResumptionStore resumptionStore;
+    private long keyRotationPeriod = TimeUnit.DAYS.toMillis(1);
+    private ResumptionKeys ticketKeys;
+    private AddressValidationTokens addressValidationTokens;
+    private RetryPolicy retryPolicy = RetryPolicy.always();
+    private long tokenLifetime = 10000;
 
     public QuicServerConnector(Server server, SslContextFactory.Server sslContextFactory, ConnectionFactory... factories)
     {
@@ -85,6 +101,7 @@ public class QuicServerConnector extends AbstractNetworkConnector
         addBean(this.sslContextFactory);
         addBean(quicConfiguration);
         addBean(container);
+        addBean(addressValidationStatistics);
         // Initialize to sane defaults for a server.
         quicConfiguration.setSessionRecvWindow(4 * 1024 * 1024);
         quicConfiguration.setBidirectionalStreamRecvWindow(2 * 1024 * 1024);
@@ -144,6 +161,125 @@ public class QuicServerConnector extends AbstractNetworkConnector
         this.useOutputDirectByteBuffers = useOutputDirectByteBuffers;
     }
 
+    /**
+     * @return the generator of server connection IDs, or null if connection IDs are random
+     */
+    public QuicLbConnectionIdGenerator getConnectionIdGenerator()
+    {
+        return connectionIdGenerator;
+    }
+
+    /**
+     * <p>Sets the generator of server connection IDs, so that they embed
+     * a server ID that QUIC-LB load balancers can route on.</p>
+     *
+     * @param connectionIdGenerator the generator of server connection IDs, or null for random connection IDs
+     */
+    public void setConnectionIdGenerator(QuicLbConnectionIdGenerator connectionIdGenerator)
+    {
+        this.connectionIdGenerator = connectionIdGenerator;
+    }
+
+    /**
+     * @return the store of the TLS session ticket keys and address validation token keys,
+     * or null if the keys are local to this connector
+     */
+    public ResumptionStore getResumptionStore()
+    {
+        return resumptionStore;
+    }
+
+    /**
+     * <p>Sets the store of the TLS session ticket keys and address validation token keys.</p>
+     * <p>Connectors sharing the same store, possibly on different nodes of a cluster,
+     * encrypt TLS session tickets and authenticate Retry tokens with the same keys,
+     * so that clients can resume their TLS sessions (and send 0-RTT early data)
+     * against any of them.</p>
+     *
+     * @param resumptionStore the store of the keys, or null if the keys are local to this connector
+     */
+    public void setResumptionStore(ResumptionStore resumptionStore)
+    {
+        this.resumptionStore = resumptionStore;
+    }
+
+    /**
+     * @return the rotation period, in milliseconds, of the keys in the {@link #getResumptionStore() store}
+     */
+    public long getKeyRotationPeriod()
+    {
+        return keyRotationPeriod;
+    }
+
+    /**
+     * <p>Sets the rotation period of the keys in the {@link #getResumptionStore() store}.</p>
+     * <p>TLS session tickets issued before the rotation of the ticket key cannot be
+     * resumed after the rotation, so the period should be longer than the TLS
+     * session lifetime.</p>
+     *
+     * @param keyRotationPeriod the rotation period of the keys, in milliseconds
+     */
+    public void setKeyRotationPeriod(long keyRotationPeriod)
+    {
+        this.keyRotationPeriod = keyRotationPeriod;
+    }
+
+    /**
+     * @return the policy that decides whether clients must validate their address with a Retry packet
+     */
+    public RetryPolicy getRetryPolicy()
+    {
+        return retryPolicy;
+    }
+
+    /**
+     * <p>Sets the policy that decides whether clients must validate their address with a Retry packet.</p>
+     * <p>The default policy {@link RetryPolicy#always() always} requires a retry.</p>
+     *
+     * @param retryPolicy the retry policy
+     */
+    public void setRetryPolicy(RetryPolicy retryPolicy)
+    {
+        this.retryPolicy = Objects.requireNonNull(retryPolicy);
+    }
+
+    /**
+     * @return the time, in milliseconds, after which the address validation tokens sent in Retry packets are not valid anymore
+     */
+    public long getTokenLifetime()
+    {
+        return tokenLifetime;
+    }
+
+    /**
+     * <p>Sets the lifetime of the address validation tokens sent in Retry packets.</p>
+     * <p>Clients echo the token immediately, so the lifetime only needs to
+     * cover the round trip to the client.</p>
+     *
+     * @param tokenLifetime the lifetime of the tokens, in milliseconds
+     */
+    public void setTokenLifetime(long tokenLifetime)
+    {
+        this.tokenLifetime = tokenLifetime;
+    }
+
+    /**
+     * @return the statistics of the address validation of the clients of this connector
+     */
+    public AddressValidationStatistics getAddressValidationStatistics()
+    {
+        return addressValidationStatistics;
+    }
+
+    /**
+     * @return the number of QUIC sessions of this connector
+     */
+    @ManagedAttribute("The number of QUIC sessions")
+    public int getQuicSessionCount()
+    {
+        return container.getSessionCount();
+    }
+
     @Override
     public boolean isOpen()
     {
@@ -156,6 +292,19 @@ public class QuicServerConnector extends AbstractNetworkConnector
     {
         for (EventListener l : getBeans(SelectorManager.SelectorManagerListener.class))
             selectorManager.addEventListener(l);
+        // The token keys are local to this connector if there is no shared store.
+        ResumptionStore tokenStore = resumptionStore;
+        if (tokenStore != null)
+        {
+            // The TLS session ticket keys of BoringSSL are 48 bytes long.
+            ticketKeys = new ResumptionKeys(tokenStore, "jetty.quic.ticketKey", 48, keyRotationPeriod);
+        }
+        else
+        {
+            tokenStore = new ResumptionStore.InMemory();
+        }
+        addressValidationTokens = new AddressValidationTokens(new ResumptionKeys(tokenStore, "jetty.quic.tokenKey", 32, keyRotationPeriod));
+        addressValidationTokens.setTokenLifetime(tokenLifetime);
         super.doStart();
         selectorManager.accept(datagramChannel);
 
@@ -229,8 +378,12 @@ public class QuicServerConnector extends AbstractNetworkConnector
         quicheConfig.setCertChainPemPath(certificateChainPemPath.toString());
         quicheConfig.setTrustedCertsPemPath(trustedCertificatesPemPath == null ? null : trustedCertificatesPemPath.toString());
         quicheConfig.setVerifyPeer(sslContextFactory.getNeedClientAuth() || sslContextFactory.getWantClientAuth());
-        // Idle timeouts must not be managed by Quiche.
-        quicheConfig.setMaxIdleTimeout(0L);
+        // Quiche writes the TLS key material itself.
+        Path keyLogFile = sslContextFactory.getEnabledKeyLogFile();
+        if (keyLogFile != null)
+            quicheConfig.setKeyLogPath(keyLogFile.toString());
+        // Idle timeouts are managed by Jetty, unless explicitly configured.
+        quicheConfig.setMaxIdleTimeout(quicConfiguration.getMaxIdleTimeout());
         quicheConfig.setInitialMaxData((long)quicConfiguration.getSessionRecvWindow());
         quicheConfig.setInitialMaxStreamDataBidiLocal((long)quicConfiguration.getBidirectionalStreamRecvWindow());
         quicheConfig.setInitialMaxStreamDataBidiRemote((long)quicConfiguration.getBidirectionalStreamRecvWindow());
@@ -238,6 +391,17 @@ public class QuicServerConnector extends AbstractNetworkConnector
         quicheConfig.setInitialMaxStreamsUni((long)quicConfiguration.getMaxUnidirectionalRemoteStreams());
         quicheConfig.setInitialMaxStreamsBidi((long)quicConfiguration.getMaxBidirectionalRemoteStreams());
         quicheConfig.setCongestionControl(QuicheConfig.CongestionControl.CUBIC);
+        quicheConfig.setDisableActiveMigration(quicConfiguration.isDisableActiveMigration());
+        if (quicConfiguration.getInitialCongestionWindowPackets() > 0)
+            quicheConfig.setInitialCongestionWindowPackets((long)quicConfiguration.getInitialCongestionWindowPackets());
+        if (quicConfiguration.isDatagramsEnabled())
+            quicheConfig.setEnableDgram(true);
+        // Early data is accepted, but it is only processed
+        // after the handshake is complete, so it cannot be replayed.
+        if (quicConfiguration.isEarlyDataEnabled())
+            quicheConfig.setEnableEarlyData(true);
+        if (ticketKeys != null)
+            quicheConfig.setTicketKey(ticketKeys.getCurrentKey());
         List<String> protocols = getProtocols();
         // This is only needed for Quiche example clients.
         protocols.add(0, "http/0.9");
@@ -245,6 +409,45 @@ public class QuicServerConnector extends AbstractNetworkConnector
         return quicheConfig;
     }
 
+    QuicheConnection.TokenMinter newTokenMinter(InetSocketAddress remoteAddress)
+    {
+        QuicheConnection.TokenMinter minter = addressValidationTokens.newTokenMinter(remoteAddress);
+        return (dcid, len) ->
+        {
+            addressValidationStatistics.onTokenIssued();
+            return minter.mint(dcid, len);
+        };
+    }
+
+    QuicheConnection.TokenValidator newTokenValidator(InetSocketAddress remoteAddress)
+    {
+        QuicheConnection.TokenValidator validator = addressValidationTokens.newTokenValidator(remoteAddress);
+        return new QuicheConnection.TokenValidator()
+        {
+            @Override
+            public byte[] validate(byte[] token, int len)
+            {
+                byte[] odcid = validator.validate(token, len);
+                if (odcid == null)
+                    addressValidationStatistics.onTokenRejected();
+                else
+                    addressValidationStatistics.onTokenValidated();
+                return odcid;
+            }
+
+            @Override
+            public boolean isRetryRequired()
+            {
+                boolean retry = retryPolicy.isRetryRequired(QuicServerConnector.this, remoteAddress);
+                if (!retry)
+                    addressValidationStatistics.onAcceptedWithoutValidation();
+                if (LOG.isDebugEnabled())
+                    LOG.debug("retry {} for {}", retry ? "required" : "not required", remoteAddress);
+                return retry;
+            }
+        };
+    }
+
     @Override
     public void setIdleTimeout(long idleTimeout)
     {
@@ -271,6 +474,9 @@ public class QuicServerConnector extends AbstractNetworkConnector
 
         for (EventListener l : getBeans(EventListener.class))
             selectorManager.removeEventListener(l);
+
+        ticketKeys = null;
+        addressValidationTokens = null;
     }
 
     private void deleteFile(Path file)
@@ -325,6 +531,7 @@ public class QuicServerConnector extends AbstractNetworkConnector
         {
             ServerQuicConnection connection = new ServerQuicConnection(QuicServerConnector.this, endpoint);
             connection.addEventListener(container);
+            connection.addEventListener(resumptionListener);
             connection.setInputBufferSize(getInputBufferSize());
             connection.setOutputBufferSize(getOutputBufferSize());
             connection.setUseInputDirectByteBuffers(isUseInputDirectByteBuffers());
@@ -351,4 +558,18 @@ public class QuicServerConnector extends AbstractNetworkConnector
             getConnectedEndPoints().forEach(endPoint -> endPoint.setIdleTimeout(idleTimeout));
         }
     }
+
+    private class ResumptionListener implements QuicSession.Listener
+    {
+        @Override
+        public void onHandshakeCompleted(QuicSession session)
+        {
+            // Report to the statistics beans, as QUIC sessions are not SslConnections.
+            List<TlsResumptionStatistics> statistics = getBeans(TlsResumptionStatistics.class);
+            if (statistics.isEmpty())
+                return;
+            boolean resumed = session.isResumed();
+            statistics.forEach(s -> s.onHandshakeSucceeded(resumed));
+        }
+    }
 }
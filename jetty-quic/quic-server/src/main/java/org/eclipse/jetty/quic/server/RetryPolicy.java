@@ -0,0 +1,128 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.net.InetSocketAddress;
+import java.util.Locale;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.InetAddressSet;
+import org.eclipse.jetty.util.StringUtil;
+
+/**
+ * <p>Decides whether a QUIC client must validate its address with a Retry
+ * packet before a connection is accepted.</p>
+ * <p>A Retry packet carries an address validation token that the client must
+ * echo in a new Initial packet, which costs the client a round trip but proves
+ * that the client can receive packets at its source address; this mitigates
+ * denial of service attacks with spoofed source addresses, and amplification
+ * attacks that use the server as a reflector.</p>
+ * <p>The policy is consulted for Initial packets without token, there is
+ * no need for a retry if the client already has a token.</p>
+ *
+ * @see QuicServerConnector#setRetryPolicy(RetryPolicy)
+ */
+@FunctionalInterface
+public interface RetryPolicy
+{
+    /**
+     * @param connector the connector receiving the Initial packet
+     * @param remoteAddress the source address of the Initial packet
+     * @return whether a Retry packet must be sent to validate the client address
+     */
+    boolean isRetryRequired(QuicServerConnector connector, InetSocketAddress remoteAddress);
+
+    /**
+     * @param other the other policy
+     * @return a policy that requires a retry if either this policy or the other policy requires it
+     */
+    default RetryPolicy or(RetryPolicy other)
+    {
+        Objects.requireNonNull(other);
+        return (connector, remoteAddress) -> isRetryRequired(connector, remoteAddress) || other.isRetryRequired(connector, remoteAddress);
+    }
+
+    /**
+     * @return a policy that always requires a retry
+     */
+    static RetryPolicy always()
+    {
+        return (connector, remoteAddress) -> true;
+    }
+
+    /**
+     * @return a policy that never requires a retry
+     */
+    static RetryPolicy never()
+    {
+        return (connector, remoteAddress) -> false;
+    }
+
+    /**
+     * @param maxSessions the number of sessions above which the connector is considered under load
+     * @return a policy that requires a retry when the connector has at least the given number of sessions
+     */
+    static RetryPolicy underLoad(int maxSessions)
+    {
+        return (connector, remoteAddress) -> connector.getQuicSessionCount() >= maxSessions;
+    }
+
+    /**
+     * @param patterns the address patterns, such as {@code 10.0.0.0/8}, as defined by {@link org.eclipse.jetty.util.InetAddressPattern}
+     * @return a policy that requires a retry for clients whose address matches any of the given patterns
+     */
+    static RetryPolicy forAddresses(String... patterns)
+    {
+        InetAddressSet addresses = new InetAddressSet();
+        for (String pattern : patterns)
+        {
+            addresses.add(pattern);
+        }
+        return (connector, remoteAddress) -> remoteAddress.getAddress() != null && addresses.test(remoteAddress.getAddress());
+    }
+
+    /**
+     * <p>Creates a policy from string configuration, typically from XML files.</p>
+     *
+     * @param mode one of {@code always}, {@code never} or {@code underLoad}
+     * @param maxSessions the number of sessions above which the connector is considered under load,
+     * for the {@code underLoad} mode
+     * @param addresses a comma separated list of address patterns for which a retry is always required,
+     * or blank for none
+     * @return a new policy
+     * @see #underLoad(int)
+     * @see #forAddresses(String...)
+     */
+    static RetryPolicy from(String mode, int maxSessions, String addresses)
+    {
+        RetryPolicy policy;
+        switch (mode.toLowerCase(Locale.ENGLISH))
+        {
+            case "always":
+                policy = always();
+                break;
+            case "never":
+                policy = never();
+                break;
+            case "underload":
+                policy = underLoad(maxSessions);
+                break;
+            default:
+                throw new IllegalArgumentException("Invalid retry policy mode " + mode);
+        }
+        if (StringUtil.isBlank(addresses))
+            return policy;
+        return policy.or(forAddresses(StringUtil.csvSplit(addresses)));
+    }
+}
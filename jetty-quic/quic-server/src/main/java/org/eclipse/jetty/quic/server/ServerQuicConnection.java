@@ -25,8 +25,6 @@ import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.quic.common.QuicConnection;
 import org.eclipse.jetty.quic.common.QuicSession;
 import org.eclipse.jetty.quic.quiche.QuicheConnection;
-import org.eclipse.jetty.quic.server.internal.SimpleTokenMinter;
-import org.eclipse.jetty.quic.server.internal.SimpleTokenValidator;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.thread.Scheduler;
@@ -61,14 +59,14 @@ public class ServerQuicConnection extends QuicConnection
     protected QuicSession createSession(SocketAddress remoteAddress, ByteBuffer cipherBuffer) throws IOException
     {
         ByteBufferPool byteBufferPool = getByteBufferPool();
-        // TODO make the token validator configurable
-        QuicheConnection quicheConnection = QuicheConnection.tryAccept(connector.newQuicheConfig(), new SimpleTokenValidator((InetSocketAddress)remoteAddress), cipherBuffer, getEndPoint().getLocalAddress(), remoteAddress);
+        QuicheConnection quicheConnection = QuicheConnection.tryAccept(connector.newQuicheConfig(), connector.newTokenValidator((InetSocketAddress)remoteAddress), cipherBuffer, getEndPoint().getLocalAddress(), remoteAddress);
         if (quicheConnection == null)
         {
             ByteBuffer negotiationBuffer = byteBufferPool.acquire(getOutputBufferSize(), true);
             int pos = BufferUtil.flipToFill(negotiationBuffer);
-            // TODO make the token minter configurable
-            if (!QuicheConnection.negotiate(new SimpleTokenMinter((InetSocketAddress)remoteAddress), cipherBuffer, negotiationBuffer))
+            QuicLbConnectionIdGenerator connectionIdGenerator = connector.getConnectionIdGenerator();
+            QuicheConnection.ConnectionIdGenerator cidGenerator = connectionIdGenerator == null ? null : connectionIdGenerator::generate;
+            if (!QuicheConnection.negotiate(connector.newTokenMinter((InetSocketAddress)remoteAddress), cidGenerator, cipherBuffer, negotiationBuffer))
             {
                 if (LOG.isDebugEnabled())
                     LOG.debug("QUIC connection negotiation failed, dropping packet");
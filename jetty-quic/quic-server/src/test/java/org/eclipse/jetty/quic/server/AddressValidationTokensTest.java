@@ -0,0 +1,73 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.net.InetSocketAddress;
+
+import org.eclipse.jetty.io.ssl.ResumptionKeys;
+import org.eclipse.jetty.io.ssl.ResumptionStore;
+import org.eclipse.jetty.quic.quiche.QuicheConnection;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.hamcrest.Matchers.nullValue;
+
+public class AddressValidationTokensTest
+{
+    private static final byte[] ODCID = {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20};
+
+    @Test
+    public void testTokenValidOnOtherNode()
+    {
+        ResumptionStore store = new ResumptionStore.InMemory();
+        AddressValidationTokens node1 = new AddressValidationTokens(new ResumptionKeys(store, "token", 32, 60000));
+        AddressValidationTokens node2 = new AddressValidationTokens(new ResumptionKeys(store, "token", 32, 60000));
+        InetSocketAddress client = new InetSocketAddress("127.0.0.1", 12345);
+
+        byte[] token = node1.newTokenMinter(client).mint(ODCID, ODCID.length);
+        assertThat(token.length, lessThanOrEqualTo(QuicheConnection.TokenMinter.MAX_TOKEN_LENGTH));
+
+        assertThat(node2.newTokenValidator(client).validate(token, token.length), is(ODCID));
+    }
+
+    @Test
+    public void testInvalidTokens()
+    {
+        ResumptionStore store = new ResumptionStore.InMemory();
+        AddressValidationTokens tokens = new AddressValidationTokens(new ResumptionKeys(store, "token", 32, 60000));
+        InetSocketAddress client = new InetSocketAddress("127.0.0.1", 12345);
+        long now = System.currentTimeMillis();
+        byte[] token = tokens.mint(client, ODCID, now);
+
+        // Different client port.
+        assertThat(tokens.validate(new InetSocketAddress("127.0.0.1", 12346), token, now), nullValue());
+
+        // Tampered token.
+        byte[] tampered = token.clone();
+        tampered[10] ^= 1;
+        assertThat(tokens.validate(client, tampered, now), nullValue());
+
+        // Different key.
+        AddressValidationTokens other = new AddressValidationTokens(new ResumptionKeys(new ResumptionStore.InMemory(), "token", 32, 60000));
+        assertThat(other.validate(client, token, now), nullValue());
+
+        // Expired token.
+        assertThat(tokens.validate(client, token, now + tokens.getTokenLifetime() + 2000), nullValue());
+
+        // Too short.
+        assertThat(tokens.validate(client, new byte[8], now), nullValue());
+    }
+}
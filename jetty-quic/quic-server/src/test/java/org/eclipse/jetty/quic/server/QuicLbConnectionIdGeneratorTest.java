@@ -0,0 +1,124 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.util.Arrays;
+
+import org.eclipse.jetty.util.StringUtil;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class QuicLbConnectionIdGeneratorTest
+{
+    private static final byte[] KEY = StringUtil.fromHexString("fdf726a9893ec05c0632d3956680baf0");
+
+    @Test
+    public void testPlaintext()
+    {
+        byte[] serverId = {0x31, 0x44, 0x1a};
+        QuicLbConnectionIdGenerator generator = new QuicLbConnectionIdGenerator(2, serverId, 6, QuicLbConnectionIdGenerator.Encryption.plaintext());
+        generator.setLengthSelfEncoding(true);
+
+        byte[] cid = generator.generate();
+        assertThat(cid.length, is(QuicLbConnectionIdGenerator.CONNECTION_ID_LENGTH));
+        assertThat(cid[0] & 0xFF, is((2 << 5) | (QuicLbConnectionIdGenerator.CONNECTION_ID_LENGTH - 1)));
+        // The server ID is in clear just after the first octet.
+        assertArrayEquals(serverId, Arrays.copyOfRange(cid, 1, 1 + serverId.length));
+        assertArrayEquals(serverId, generator.decodeServerId(cid));
+    }
+
+    @ParameterizedTest
+    @ValueSource(ints = {4, 5, 8, 12, 13, 16})
+    public void testAesRoundTrip(int nonceLength)
+    {
+        // With a 3 bytes server ID, a 13 bytes nonce selects the single-pass algorithm,
+        // other lengths select the four-pass algorithm with both odd and even lengths.
+        byte[] serverId = {0x31, 0x44, 0x1a};
+        QuicLbConnectionIdGenerator generator = new QuicLbConnectionIdGenerator(0, serverId, nonceLength, QuicLbConnectionIdGenerator.Encryption.aes(KEY));
+
+        for (int i = 0; i < 64; ++i)
+        {
+            byte[] cid = generator.generate();
+            assertThat(cid.length, is(QuicLbConnectionIdGenerator.CONNECTION_ID_LENGTH));
+            assertThat((cid[0] & 0xFF) >>> 5, is(0));
+            assertArrayEquals(serverId, generator.decodeServerId(cid));
+        }
+    }
+
+    @ParameterizedTest
+    @ValueSource(ints = {1, 2, 7, 9, 10, 11, 14, 15, 16, 17, 18, 19})
+    public void testAesPreservesLength(int length)
+    {
+        QuicLbConnectionIdGenerator.Encryption encryption = QuicLbConnectionIdGenerator.Encryption.aes(KEY);
+        byte[] plaintext = new byte[length];
+        for (int i = 0; i < length; ++i)
+        {
+            plaintext[i] = (byte)(i * 37 + 11);
+        }
+
+        byte[] ciphertext = encryption.encrypt(plaintext);
+        assertThat(ciphertext.length, is(length));
+        assertArrayEquals(plaintext, encryption.decrypt(ciphertext));
+    }
+
+    @Test
+    public void testDecodeOtherServerId()
+    {
+        QuicLbConnectionIdGenerator generator1 = new QuicLbConnectionIdGenerator(1, new byte[]{0x01, 0x02}, 8, QuicLbConnectionIdGenerator.Encryption.aes(KEY));
+        QuicLbConnectionIdGenerator generator2 = new QuicLbConnectionIdGenerator(1, new byte[]{0x01, 0x03}, 8, QuicLbConnectionIdGenerator.Encryption.aes(KEY));
+
+        byte[] cid = generator2.generate();
+        assertArrayEquals(new byte[]{0x01, 0x03}, generator1.decodeServerId(cid));
+    }
+
+    @Test
+    public void testConfigRotationMismatch()
+    {
+        QuicLbConnectionIdGenerator generator1 = new QuicLbConnectionIdGenerator(1, new byte[]{0x01}, 8, QuicLbConnectionIdGenerator.Encryption.plaintext());
+        QuicLbConnectionIdGenerator generator2 = new QuicLbConnectionIdGenerator(3, new byte[]{0x01}, 8, QuicLbConnectionIdGenerator.Encryption.plaintext());
+
+        assertThat(generator1.decodeServerId(generator2.generate()), nullValue());
+        assertThat(generator1.decodeServerId(new byte[4]), nullValue());
+    }
+
+    @Test
+    public void testFrom()
+    {
+        assertThat(QuicLbConnectionIdGenerator.from("", 0, 8, ""), nullValue());
+
+        QuicLbConnectionIdGenerator generator = QuicLbConnectionIdGenerator.from("0a0b", 4, 6, StringUtil.toHexString(KEY));
+        assertThat(generator.getConfigRotation(), is(4));
+        assertThat(generator.getNonceLength(), is(6));
+        assertArrayEquals(new byte[]{0x0a, 0x0b}, generator.getServerId());
+        assertArrayEquals(new byte[]{0x0a, 0x0b}, generator.decodeServerId(generator.generate()));
+    }
+
+    @Test
+    public void testInvalidConfiguration()
+    {
+        QuicLbConnectionIdGenerator.Encryption plaintext = QuicLbConnectionIdGenerator.Encryption.plaintext();
+        assertThrows(IllegalArgumentException.class, () -> new QuicLbConnectionIdGenerator(7, new byte[]{1}, 8, plaintext));
+        assertThrows(IllegalArgumentException.class, () -> new QuicLbConnectionIdGenerator(0, new byte[0], 8, plaintext));
+        assertThrows(IllegalArgumentException.class, () -> new QuicLbConnectionIdGenerator(0, new byte[]{1}, 3, plaintext));
+        assertThrows(IllegalArgumentException.class, () -> new QuicLbConnectionIdGenerator(0, new byte[15], 5, plaintext));
+        assertThrows(IllegalArgumentException.class, () -> QuicLbConnectionIdGenerator.Encryption.aes(new byte[8]));
+    }
+}
@@ -0,0 +1,67 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.quic.server;
+
+import java.net.InetSocketAddress;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.junit.jupiter.api.Test;
+
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class RetryPolicyTest
+{
+    private final QuicServerConnector connector = new QuicServerConnector(new Server(), new SslContextFactory.Server());
+    private final InetSocketAddress internal = new InetSocketAddress("10.1.2.3", 12345);
+    private final InetSocketAddress external = new InetSocketAddress("192.0.2.1", 12345);
+
+    @Test
+    public void testAlwaysAndNever()
+    {
+        assertTrue(RetryPolicy.always().isRetryRequired(connector, external));
+        assertFalse(RetryPolicy.never().isRetryRequired(connector, external));
+    }
+
+    @Test
+    public void testUnderLoad()
+    {
+        // The connector has no sessions.
+        assertTrue(RetryPolicy.underLoad(0).isRetryRequired(connector, external));
+        assertFalse(RetryPolicy.underLoad(1).isRetryRequired(connector, external));
+    }
+
+    @Test
+    public void testForAddresses()
+    {
+        RetryPolicy policy = RetryPolicy.forAddresses("10.0.0.0/8", "198.51.100.1");
+        assertTrue(policy.isRetryRequired(connector, internal));
+        assertTrue(policy.isRetryRequired(connector, new InetSocketAddress("198.51.100.1", 443)));
+        assertFalse(policy.isRetryRequired(connector, external));
+    }
+
+    @Test
+    public void testFromConfiguration()
+    {
+        RetryPolicy policy = RetryPolicy.from("underLoad", 1, "10.0.0.0/8, 192.168.0.0/16");
+        assertTrue(policy.isRetryRequired(connector, internal));
+        assertFalse(policy.isRetryRequired(connector, external));
+
+        assertTrue(RetryPolicy.from("always", 0, "").isRetryRequired(connector, external));
+        assertFalse(RetryPolicy.from("NEVER", 0, null).isRetryRequired(connector, external));
+        assertThrows(IllegalArgumentException.class, () -> RetryPolicy.from("sometimes", 0, null));
+    }
+}
@@ -0,0 +1,60 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables session data store in Redis.
+
+[tags]
+session
+
+[provides]
+session-store
+
+[depend]
+sessions
+
+[files]
+maven://redis.clients/jedis/${jedis.version}|lib/redis/jedis-${jedis.version}.jar
+maven://org.apache.commons/commons-pool2/${commons-pool2.version}|lib/redis/commons-pool2-${commons-pool2.version}.jar
+maven://org.json/json/${org.json.version}|lib/redis/json-${org.json.version}.jar
+maven://com.google.code.gson/gson/${gson.version}|lib/redis/gson-${gson.version}.jar
+
+[xml]
+etc/sessions/redis/session-store.xml
+
+[lib]
+lib/jetty-redis-${jetty.version}.jar
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/redis/*.jar
+
+[ini]
+jedis.version?=@jedis.version@
+commons-pool2.version?=2.11.1
+org.json.version?=20230227
+gson.version?=@gson.version@
+
+[license]
+Jedis is an open source project hosted on Github and released under the MIT license.
+    https://github.com/redis/jedis
+https://opensource.org/licenses/MIT
+
+
+[ini-template]
+jetty.session.redis.addresses=localhost:6379
+#jetty.session.redis.cluster=false
+#jetty.session.redis.sentinelMasterName=
+#jetty.session.redis.user=
+#jetty.session.redis.password=
+#jetty.session.redis.database=0
+#jetty.session.redis.ssl=false
+#jetty.session.redis.timeoutMs=2000
+#jetty.session.redis.keyPrefix=jetty-session:
+jetty.session.gracePeriod.seconds=3600
+jetty.session.savePeriod.seconds=0
+## Name of the SessionDataCodec that serializes the session attributes, such as java or json
+#jetty.session.serialization.codec=java
+## Content coding used to compress the session attributes, such as gzip
+#jetty.session.serialization.compression=
+## Min serialized size in bytes of the session attributes to compress them
+#jetty.session.serialization.compressionThreshold=1024
+## Serialized size in bytes above which a warning is logged, -1 to never warn
+#jetty.session.serialization.warnSize=-1
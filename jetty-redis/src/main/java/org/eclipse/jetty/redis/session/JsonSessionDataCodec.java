@@ -0,0 +1,81 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Map;
+
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataCodec;
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>The {@code json} {@link SessionDataCodec}, that encodes the session attributes
+ * as a JSON object, so that they can be read by non-Java applications.</p>
+ * <p>Session attribute values must be representable in JSON: strings,
+ * numbers, booleans, arrays, maps, or classes for which a {@link JSON.Convertor}
+ * has been registered on the {@link #getJSON() JSON} instance.
+ * Note that numbers are decoded as {@code Long} or {@code Double}
+ * and arrays as {@code Object[]}.</p>
+ * <p>This codec is discovered by {@link SessionDataCodec#getSessionDataCodecs()},
+ * so it can be used by any session data store when this module is available.</p>
+ */
+public class JsonSessionDataCodec extends SessionDataCodec
+{
+    private final JSON json;
+
+    public JsonSessionDataCodec()
+    {
+        this(new JSON());
+    }
+
+    public JsonSessionDataCodec(JSON json)
+    {
+        super("json");
+        this.json = json;
+    }
+
+    /**
+     * @return the JSON instance used to convert session attributes
+     */
+    public JSON getJSON()
+    {
+        return json;
+    }
+
+    @Override
+    public byte[] encode(SessionData data) throws IOException
+    {
+        return json.toJSON(data.getAllAttributes()).getBytes(StandardCharsets.UTF_8);
+    }
+
+    @Override
+    @SuppressWarnings("unchecked")
+    public void decode(SessionData data, byte[] bytes) throws IOException
+    {
+        Object attributes;
+        try
+        {
+            attributes = json.fromJSON(new String(bytes, StandardCharsets.UTF_8));
+        }
+        catch (IllegalArgumentException | IllegalStateException x)
+        {
+            throw new IOException("Invalid session attributes", x);
+        }
+        if (!(attributes instanceof Map))
+            throw new IOException("Invalid session attributes " + attributes);
+        data.putAllAttributes((Map<String, Object>)attributes);
+    }
+}
@@ -0,0 +1,77 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataSerialization;
+
+/**
+ * <p>The format of the session data stored in Redis: the session metadata,
+ * in the same layout as the {@link org.eclipse.jetty.server.session.FileSessionDataStore},
+ * followed by the attributes serialized by a {@link SessionDataSerialization}.</p>
+ */
+final class RedisSessionData
+{
+    private RedisSessionData()
+    {
+    }
+
+    static byte[] serialize(SessionDataSerialization serialization, SessionData data) throws IOException
+    {
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+        DataOutputStream out = new DataOutputStream(bytes);
+        out.writeUTF(data.getId());
+        out.writeUTF(data.getContextPath());
+        out.writeUTF(data.getVhost());
+        out.writeUTF(data.getLastNode());
+        out.writeLong(data.getCreated());
+        out.writeLong(data.getAccessed());
+        out.writeLong(data.getLastAccessed());
+        out.writeLong(data.getCookieSet());
+        out.writeLong(data.getExpiry());
+        out.writeLong(data.getMaxInactiveMs());
+        out.flush();
+        serialization.serializeAttributes(data, bytes);
+        return bytes.toByteArray();
+    }
+
+    static SessionData deserialize(SessionDataSerialization serialization, byte[] bytes) throws IOException
+    {
+        ByteArrayInputStream is = new ByteArrayInputStream(bytes);
+        DataInputStream in = new DataInputStream(is);
+        String id = in.readUTF();
+        String contextPath = in.readUTF();
+        String vhost = in.readUTF();
+        String lastNode = in.readUTF();
+        long created = in.readLong();
+        long accessed = in.readLong();
+        long lastAccessed = in.readLong();
+        long cookieSet = in.readLong();
+        long expiry = in.readLong();
+        long maxInactive = in.readLong();
+
+        SessionData data = new SessionData(id, contextPath, vhost, created, accessed, lastAccessed, maxInactive);
+        data.setLastNode(lastNode);
+        data.setCookieSet(cookieSet);
+        data.setExpiry(expiry);
+        serialization.deserializeAttributes(data, is);
+        return data;
+    }
+}
@@ -0,0 +1,160 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.nio.charset.StandardCharsets;
+import java.util.Objects;
+
+import org.eclipse.jetty.server.session.SessionContext;
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataMap;
+import org.eclipse.jetty.server.session.SessionDataSerialization;
+import org.eclipse.jetty.util.FuturePromise;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import redis.clients.jedis.UnifiedJedis;
+import redis.clients.jedis.params.SetParams;
+
+/**
+ * RedisSessionDataMap
+ *
+ * Uses Redis as a cache for SessionData, to be used with a
+ * {@link org.eclipse.jetty.server.session.CachingSessionDataStore}.
+ * The session data is stored in the same format as the {@link RedisSessionDataStore}.
+ */
+@ManagedObject
+public class RedisSessionDataMap extends AbstractLifeCycle implements SessionDataMap
+{
+    protected final UnifiedJedis _jedis;
+    protected SessionDataSerialization _serialization = new SessionDataSerialization();
+    protected String _keyPrefix = RedisSessionDataStore.DEFAULT_KEY_PREFIX;
+    protected int _expirySec = 0;
+    protected SessionContext _context;
+
+    /**
+     * @param jedis the Redis client, which is not closed when this map is stopped
+     */
+    public RedisSessionDataMap(UnifiedJedis jedis)
+    {
+        _jedis = Objects.requireNonNull(jedis);
+    }
+
+    /**
+     * @param serialization the serialization of the session attributes
+     */
+    public void setSessionDataSerialization(SessionDataSerialization serialization)
+    {
+        _serialization = Objects.requireNonNull(serialization);
+    }
+
+    public SessionDataSerialization getSessionDataSerialization()
+    {
+        return _serialization;
+    }
+
+    public void setKeyPrefix(String keyPrefix)
+    {
+        _keyPrefix = keyPrefix == null ? "" : keyPrefix;
+    }
+
+    @ManagedAttribute(value = "the prefix of Redis keys", readonly = true)
+    public String getKeyPrefix()
+    {
+        return _keyPrefix;
+    }
+
+    /**
+     * @param sec the Redis TTL of cached entries in seconds, or 0 for no TTL
+     */
+    public void setExpirySec(int sec)
+    {
+        _expirySec = sec;
+    }
+
+    @ManagedAttribute(value = "redis expiry time in sec", readonly = true)
+    public int getExpirySec()
+    {
+        return _expirySec;
+    }
+
+    @Override
+    public void initialize(SessionContext context)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Context set after RedisSessionDataMap started");
+        _context = context;
+    }
+
+    @Override
+    public SessionData load(String id) throws Exception
+    {
+        if (!isStarted())
+            throw new IllegalStateException("Not started");
+
+        final FuturePromise<SessionData> result = new FuturePromise<>();
+        Runnable r = () ->
+        {
+            try
+            {
+                byte[] bytes = _jedis.get(getCacheKey(id));
+                result.succeeded(bytes == null ? null : RedisSessionData.deserialize(_serialization, bytes));
+            }
+            catch (Exception e)
+            {
+                result.failed(e);
+            }
+        };
+        _context.run(r);
+        return result.getOrThrow();
+    }
+
+    @Override
+    public void store(String id, SessionData data) throws Exception
+    {
+        if (!isStarted())
+            throw new IllegalStateException("Not started");
+
+        final FuturePromise<Void> result = new FuturePromise<>();
+        Runnable r = () ->
+        {
+            try
+            {
+                SetParams params = SetParams.setParams();
+                if (_expirySec > 0)
+                    params.ex(_expirySec);
+                _jedis.set(getCacheKey(id), RedisSessionData.serialize(_serialization, data), params);
+                result.succeeded(null);
+            }
+            catch (Exception e)
+            {
+                result.failed(e);
+            }
+        };
+        _context.run(r);
+        result.getOrThrow();
+    }
+
+    @Override
+    public boolean delete(String id) throws Exception
+    {
+        return _jedis.del(getCacheKey(id)) > 0;
+    }
+
+    private byte[] getCacheKey(String id)
+    {
+        String key = _keyPrefix + "cache:" + _context.getCanonicalContextPath() + "_" + _context.getVhost() + "_" + id;
+        return key.getBytes(StandardCharsets.UTF_8);
+    }
+}
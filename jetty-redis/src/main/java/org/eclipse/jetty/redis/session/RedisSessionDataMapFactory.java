@@ -0,0 +1,101 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import org.eclipse.jetty.server.session.SessionDataMap;
+import org.eclipse.jetty.server.session.SessionDataMapFactory;
+import redis.clients.jedis.UnifiedJedis;
+
+/**
+ * RedisSessionDataMapFactory
+ */
+public class RedisSessionDataMapFactory implements SessionDataMapFactory
+{
+    protected UnifiedJedis _jedis;
+    protected int _expirySec;
+    protected String _keyPrefix = RedisSessionDataStore.DEFAULT_KEY_PREFIX;
+    protected String _serializationCodec;
+    protected String _serializationCompression;
+
+    /**
+     * @param jedis the Redis client shared by the session data maps
+     */
+    public void setJedis(UnifiedJedis jedis)
+    {
+        _jedis = jedis;
+    }
+
+    public int getExpirySec()
+    {
+        return _expirySec;
+    }
+
+    /**
+     * @param expiry time in secs that redis entries remain valid
+     */
+    public void setExpirySec(int expiry)
+    {
+        _expirySec = expiry;
+    }
+
+    public String getKeyPrefix()
+    {
+        return _keyPrefix;
+    }
+
+    public void setKeyPrefix(String keyPrefix)
+    {
+        _keyPrefix = keyPrefix;
+    }
+
+    public String getSerializationCodec()
+    {
+        return _serializationCodec;
+    }
+
+    /**
+     * @param codec the name of the {@link org.eclipse.jetty.server.session.SessionDataCodec}
+     * of the session attributes, or null for {@code java}
+     */
+    public void setSerializationCodec(String codec)
+    {
+        _serializationCodec = codec;
+    }
+
+    public String getSerializationCompression()
+    {
+        return _serializationCompression;
+    }
+
+    /**
+     * @param compression the content coding used to compress the session attributes, or null for no compression
+     */
+    public void setSerializationCompression(String compression)
+    {
+        _serializationCompression = compression;
+    }
+
+    @Override
+    public SessionDataMap getSessionDataMap()
+    {
+        if (_jedis == null)
+            throw new IllegalStateException("No Redis client");
+        RedisSessionDataMap m = new RedisSessionDataMap(_jedis);
+        m.setExpirySec(_expirySec);
+        m.setKeyPrefix(_keyPrefix);
+        m.getSessionDataSerialization().setCodecName(_serializationCodec);
+        m.getSessionDataSerialization().setCompression(_serializationCompression);
+        return m;
+    }
+}
@@ -0,0 +1,270 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.nio.charset.StandardCharsets;
+import java.util.Collections;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Set;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.server.session.AbstractSessionDataStore;
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataStore;
+import org.eclipse.jetty.server.session.UnreadableSessionDataException;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+import redis.clients.jedis.UnifiedJedis;
+import redis.clients.jedis.params.SetParams;
+
+/**
+ * <p>Session data stored in Redis.</p>
+ * <p>Each session is stored under the key {@code <keyPrefix><context>_<vhost>_<id>}
+ * with a Redis TTL of the remaining session lifetime plus the
+ * {@link #getGracePeriodSec() grace period}, so that Redis eventually evicts
+ * sessions that no node expires.
+ * The expiry time of every session is also recorded in a sorted set named
+ * {@code <keyPrefix>expiry}, which is used to find expired and orphaned sessions
+ * without scanning the keyspace.</p>
+ * <p>The session attributes are serialized by the
+ * {@link #getSessionDataSerialization() session data serialization}, which may use
+ * any available {@link org.eclipse.jetty.server.session.SessionDataCodec}, such as
+ * the {@link JsonSessionDataCodec json} codec.</p>
+ * <p>The {@link UnifiedJedis} client may be a standalone, sentinel or cluster client.</p>
+ */
+@ManagedObject
+public class RedisSessionDataStore extends AbstractSessionDataStore
+    implements SessionDataStore
+{
+    public static final String DEFAULT_KEY_PREFIX = "jetty-session:";
+
+    private static final Logger LOG = LoggerFactory.getLogger(RedisSessionDataStore.class);
+
+    private UnifiedJedis _jedis;
+    private String _keyPrefix = DEFAULT_KEY_PREFIX;
+
+    public UnifiedJedis getJedis()
+    {
+        return _jedis;
+    }
+
+    /**
+     * @param jedis the Redis client, which is not closed when this store is stopped
+     */
+    public void setJedis(UnifiedJedis jedis)
+    {
+        _jedis = jedis;
+    }
+
+    @ManagedAttribute(value = "the prefix of Redis keys", readonly = true)
+    public String getKeyPrefix()
+    {
+        return _keyPrefix;
+    }
+
+    /**
+     * @param keyPrefix the prefix of all the Redis keys used by this store
+     */
+    public void setKeyPrefix(String keyPrefix)
+    {
+        _keyPrefix = keyPrefix == null ? "" : keyPrefix;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_jedis == null)
+            throw new IllegalStateException("No Redis client");
+        super.doStart();
+    }
+
+    @Override
+    public SessionData doLoad(String id) throws Exception
+    {
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Loading session {} from redis", id);
+
+            byte[] bytes = _jedis.get(getCacheKey(id).getBytes(StandardCharsets.UTF_8));
+            if (bytes == null)
+                return null;
+            return RedisSessionData.deserialize(getSessionDataSerialization(), bytes);
+        }
+        catch (Exception e)
+        {
+            throw new UnreadableSessionDataException(id, _context, e);
+        }
+    }
+
+    @Override
+    public void doStore(String id, SessionData data, long lastSaveTime) throws Exception
+    {
+        String key = getCacheKey(id);
+        byte[] bytes = RedisSessionData.serialize(getSessionDataSerialization(), data);
+        SetParams params = SetParams.setParams();
+        long expiry = data.getExpiry();
+        if (expiry > 0)
+        {
+            long ttl = Math.max(1, expiry - System.currentTimeMillis()) + TimeUnit.SECONDS.toMillis(getGracePeriodSec());
+            params.px(ttl);
+        }
+        _jedis.set(key.getBytes(StandardCharsets.UTF_8), bytes, params);
+
+        if (expiry > 0)
+            _jedis.zadd(getExpiryKey(), expiry, key);
+        else
+            _jedis.zrem(getExpiryKey(), key);
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Stored session {} in redis with expiry {}", id, expiry);
+    }
+
+    @Override
+    public boolean delete(String id) throws Exception
+    {
+        String key = getCacheKey(id);
+        long deleted = _jedis.del(key);
+        _jedis.zrem(getExpiryKey(), key);
+        return deleted > 0;
+    }
+
+    @Override
+    public boolean doExists(String id) throws Exception
+    {
+        String key = getCacheKey(id);
+        if (!_jedis.exists(key))
+            return false;
+        Double expiry = _jedis.zscore(getExpiryKey(), key);
+        if (expiry == null)
+            return true; //never expires
+        return expiry.longValue() > System.currentTimeMillis(); //not expired yet
+    }
+
+    @Override
+    public Set<String> doCheckExpired(Set<String> candidates, long time)
+    {
+        Set<String> expired = new HashSet<>();
+        for (String candidate : candidates)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Checking expiry for candidate {}", candidate);
+
+            try
+            {
+                SessionData sd = load(candidate);
+
+                //if the session no longer exists
+                if (sd == null)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Session {} does not exist in redis", candidate);
+                    expired.add(candidate);
+                }
+                else if (_context.getWorkerName().equals(sd.getLastNode()))
+                {
+                    //we are its manager, add it to the expired set if it is expired now
+                    if ((sd.getExpiry() > 0) && sd.getExpiry() <= time)
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Session {} managed by {} is expired", candidate, _context.getWorkerName());
+                        expired.add(candidate);
+                    }
+                }
+            }
+            catch (Exception e)
+            {
+                LOG.warn("Error checking if candidate {} is expired so expire it", candidate, e);
+                expired.add(candidate);
+            }
+        }
+        return expired;
+    }
+
+    @Override
+    public Set<String> doGetExpired(long before)
+    {
+        try
+        {
+            String prefix = getCacheKey("");
+            Set<String> ids = new HashSet<>();
+            for (String key : _jedis.zrangeByScore(getExpiryKey(), 1, before))
+            {
+                if (key.startsWith(prefix))
+                    ids.add(key.substring(prefix.length()));
+            }
+            return ids;
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Error querying for expired sessions", e);
+            return Collections.emptySet();
+        }
+    }
+
+    @Override
+    public void doCleanOrphans(long time)
+    {
+        try
+        {
+            List<String> keys = _jedis.zrangeByScore(getExpiryKey(), 1, time);
+            if (keys.isEmpty())
+                return;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Cleaning {} orphaned sessions expired before {}", keys.size(), time);
+            //delete key by key, as in a cluster the keys may live in different slots
+            for (String key : keys)
+            {
+                _jedis.del(key);
+            }
+            _jedis.zrem(getExpiryKey(), keys.toArray(new String[0]));
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Error cleaning orphaned sessions", e);
+        }
+    }
+
+    @Override
+    public boolean isPassivating()
+    {
+        return true;
+    }
+
+    /**
+     * @param id the session id
+     * @return the Redis key of the session in this context
+     */
+    public String getCacheKey(String id)
+    {
+        return _keyPrefix + _context.getCanonicalContextPath() + "_" + _context.getVhost() + "_" + id;
+    }
+
+    /**
+     * @return the Redis key of the sorted set of session expiry times
+     */
+    public String getExpiryKey()
+    {
+        return _keyPrefix + "expiry";
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s[keyPrefix=%s,serialization=%s]", super.toString(), _keyPrefix, getSessionDataSerialization());
+    }
+}
@@ -0,0 +1,228 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.util.Arrays;
+import java.util.Set;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.server.session.AbstractSessionDataStoreFactory;
+import org.eclipse.jetty.server.session.SessionDataStore;
+import org.eclipse.jetty.server.session.SessionDataStoreFactory;
+import org.eclipse.jetty.server.session.SessionHandler;
+import org.eclipse.jetty.util.StringUtil;
+import redis.clients.jedis.DefaultJedisClientConfig;
+import redis.clients.jedis.HostAndPort;
+import redis.clients.jedis.JedisClientConfig;
+import redis.clients.jedis.JedisCluster;
+import redis.clients.jedis.JedisPooled;
+import redis.clients.jedis.JedisSentineled;
+import redis.clients.jedis.UnifiedJedis;
+
+/**
+ * <p>Factory to construct {@link RedisSessionDataStore}.</p>
+ * <p>The Redis client is created on first use and shared by all the session
+ * data stores created by this factory; depending on the configuration it
+ * connects to a standalone Redis server, to the master of a set of
+ * {@link #setSentinelMasterName(String) sentinels} or to a
+ * {@link #setCluster(boolean) cluster}.</p>
+ */
+public class RedisSessionDataStoreFactory
+    extends AbstractSessionDataStoreFactory
+    implements SessionDataStoreFactory
+{
+    private String _addresses = "localhost:6379";
+    private boolean _cluster;
+    private String _sentinelMasterName;
+    private String _user;
+    private String _password;
+    private int _database;
+    private boolean _ssl;
+    private int _timeoutMs = 2000;
+    private String _keyPrefix = RedisSessionDataStore.DEFAULT_KEY_PREFIX;
+    private UnifiedJedis _jedis;
+
+    @Override
+    public SessionDataStore getSessionDataStore(SessionHandler handler) throws Exception
+    {
+        RedisSessionDataStore store = new RedisSessionDataStore();
+        store.setJedis(getJedis());
+        store.setKeyPrefix(getKeyPrefix());
+        store.setGracePeriodSec(getGracePeriodSec());
+        store.setSavePeriodSec(getSavePeriodSec());
+        configure(store.getSessionDataSerialization());
+        return store;
+    }
+
+    /**
+     * @return the Redis client, created if necessary
+     */
+    public synchronized UnifiedJedis getJedis()
+    {
+        if (_jedis == null)
+            _jedis = newJedis();
+        return _jedis;
+    }
+
+    /**
+     * @param jedis the Redis client to use instead of one created from the configuration
+     */
+    public synchronized void setJedis(UnifiedJedis jedis)
+    {
+        _jedis = jedis;
+    }
+
+    protected UnifiedJedis newJedis()
+    {
+        if (StringUtil.isBlank(getAddresses()))
+            throw new IllegalStateException("No Redis addresses");
+        Set<HostAndPort> hosts = Arrays.stream(StringUtil.csvSplit(getAddresses()))
+            .map(String::trim)
+            .filter(s -> !s.isEmpty())
+            .map(HostAndPort::from)
+            .collect(Collectors.toSet());
+
+        JedisClientConfig config = DefaultJedisClientConfig.builder()
+            .user(StringUtil.isEmpty(_user) ? null : _user)
+            .password(StringUtil.isEmpty(_password) ? null : _password)
+            .database(_database)
+            .ssl(_ssl)
+            .timeoutMillis(_timeoutMs)
+            .build();
+
+        if (isCluster())
+            return new JedisCluster(hosts, config);
+        if (!StringUtil.isEmpty(getSentinelMasterName()))
+        {
+            JedisClientConfig sentinelConfig = DefaultJedisClientConfig.builder()
+                .ssl(_ssl)
+                .timeoutMillis(_timeoutMs)
+                .build();
+            return new JedisSentineled(getSentinelMasterName(), config, hosts, sentinelConfig);
+        }
+        if (hosts.size() > 1)
+            throw new IllegalStateException("Multiple Redis addresses require cluster or sentinel mode: " + getAddresses());
+        return new JedisPooled(hosts.iterator().next(), config);
+    }
+
+    public String getAddresses()
+    {
+        return _addresses;
+    }
+
+    /**
+     * @param addresses comma separated list of {@code host:port} of the
+     * Redis server, of the sentinels or of the cluster nodes
+     */
+    public void setAddresses(String addresses)
+    {
+        _addresses = addresses;
+    }
+
+    public boolean isCluster()
+    {
+        return _cluster;
+    }
+
+    /**
+     * @param cluster whether the addresses are nodes of a Redis cluster
+     */
+    public void setCluster(boolean cluster)
+    {
+        _cluster = cluster;
+    }
+
+    public String getSentinelMasterName()
+    {
+        return _sentinelMasterName;
+    }
+
+    /**
+     * @param sentinelMasterName the name of the master monitored by the sentinels,
+     * in which case the addresses are those of the sentinels
+     */
+    public void setSentinelMasterName(String sentinelMasterName)
+    {
+        _sentinelMasterName = sentinelMasterName;
+    }
+
+    public String getUser()
+    {
+        return _user;
+    }
+
+    public void setUser(String user)
+    {
+        _user = user;
+    }
+
+    public String getPassword()
+    {
+        return _password;
+    }
+
+    public void setPassword(String password)
+    {
+        _password = password;
+    }
+
+    public int getDatabase()
+    {
+        return _database;
+    }
+
+    /**
+     * @param database the Redis database index, not supported in cluster mode
+     */
+    public void setDatabase(int database)
+    {
+        _database = database;
+    }
+
+    public boolean isSsl()
+    {
+        return _ssl;
+    }
+
+    public void setSsl(boolean ssl)
+    {
+        _ssl = ssl;
+    }
+
+    public int getTimeoutMs()
+    {
+        return _timeoutMs;
+    }
+
+    /**
+     * @param timeoutMs the connect and socket timeout in milliseconds
+     */
+    public void setTimeoutMs(int timeoutMs)
+    {
+        _timeoutMs = timeoutMs;
+    }
+
+    public String getKeyPrefix()
+    {
+        return _keyPrefix;
+    }
+
+    /**
+     * @param keyPrefix the prefix of all the Redis keys used by the session data stores
+     */
+    public void setKeyPrefix(String keyPrefix)
+    {
+        _keyPrefix = keyPrefix;
+    }
+}
@@ -0,0 +1,84 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.ssl;
+
+import java.nio.charset.StandardCharsets;
+import java.util.Objects;
+
+import org.eclipse.jetty.io.ssl.ResumptionStore;
+import redis.clients.jedis.UnifiedJedis;
+import redis.clients.jedis.params.SetParams;
+
+/**
+ * <p>A {@link ResumptionStore} backed by Redis, so that the TLS session
+ * ticket keys and the QUIC address validation token keys are shared
+ * by all the nodes of a cluster that use the same Redis deployment.</p>
+ * <p>The values are secret material, so the Redis deployment should
+ * require authentication and use TLS.</p>
+ */
+public class RedisResumptionStore implements ResumptionStore
+{
+    public static final String DEFAULT_KEY_PREFIX = "jetty:resumption:";
+
+    private final UnifiedJedis jedis;
+    private String keyPrefix = DEFAULT_KEY_PREFIX;
+
+    /**
+     * @param jedis the Redis client, which is not closed by this store
+     */
+    public RedisResumptionStore(UnifiedJedis jedis)
+    {
+        this.jedis = Objects.requireNonNull(jedis);
+    }
+
+    public String getKeyPrefix()
+    {
+        return keyPrefix;
+    }
+
+    public void setKeyPrefix(String keyPrefix)
+    {
+        this.keyPrefix = keyPrefix == null ? "" : keyPrefix;
+    }
+
+    @Override
+    public byte[] get(String key)
+    {
+        return jedis.get(toRedisKey(key));
+    }
+
+    @Override
+    public boolean putIfAbsent(String key, byte[] value, long ttl)
+    {
+        SetParams params = SetParams.setParams().nx().px(ttl);
+        return "OK".equals(jedis.set(toRedisKey(key), value, params));
+    }
+
+    @Override
+    public void remove(String key)
+    {
+        jedis.del(toRedisKey(key));
+    }
+
+    private byte[] toRedisKey(String key)
+    {
+        return (keyPrefix + key).getBytes(StandardCharsets.UTF_8);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[prefix=%s]", getClass().getSimpleName(), hashCode(), keyPrefix);
+    }
+}
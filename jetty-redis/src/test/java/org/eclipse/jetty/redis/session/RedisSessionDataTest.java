@@ -0,0 +1,100 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.util.Arrays;
+import java.util.stream.Stream;
+
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataCodec;
+import org.eclipse.jetty.server.session.SessionDataSerialization;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.Arguments;
+import org.junit.jupiter.params.provider.MethodSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+
+public class RedisSessionDataTest
+{
+    public static Stream<Arguments> serializations()
+    {
+        return Stream.of(
+            Arguments.of("java", null),
+            Arguments.of("json", null),
+            Arguments.of("java", "gzip"),
+            Arguments.of("json", "gzip")
+        );
+    }
+
+    @Test
+    public void testJsonCodecIsDiscovered()
+    {
+        assertThat(SessionDataCodec.getSessionDataCodec("json"), instanceOf(JsonSessionDataCodec.class));
+    }
+
+    @ParameterizedTest
+    @MethodSource("serializations")
+    public void testRoundTrip(String codec, String compression) throws Exception
+    {
+        SessionDataSerialization serialization = new SessionDataSerialization();
+        serialization.setCodecName(codec);
+        serialization.setCompression(compression);
+        serialization.setCompressionThreshold(0);
+
+        SessionData data = new SessionData("1234", "_test", "0.0.0.0", 100, 200, 150, 30000);
+        data.setLastNode("node0");
+        data.setCookieSet(120);
+        data.setExpiry(230000);
+        data.setAttribute("name", "value");
+        data.setAttribute("count", 42L);
+        data.setAttribute("flag", true);
+
+        SessionData result = RedisSessionData.deserialize(serialization, RedisSessionData.serialize(serialization, data));
+
+        assertEquals(data.getId(), result.getId());
+        assertEquals(data.getContextPath(), result.getContextPath());
+        assertEquals(data.getVhost(), result.getVhost());
+        assertEquals(data.getLastNode(), result.getLastNode());
+        assertEquals(data.getCreated(), result.getCreated());
+        assertEquals(data.getAccessed(), result.getAccessed());
+        assertEquals(data.getLastAccessed(), result.getLastAccessed());
+        assertEquals(data.getCookieSet(), result.getCookieSet());
+        assertEquals(data.getExpiry(), result.getExpiry());
+        assertEquals(data.getMaxInactiveMs(), result.getMaxInactiveMs());
+        assertThat(result.getKeys(), is(data.getKeys()));
+        for (String name : Arrays.asList("name", "count", "flag"))
+        {
+            assertEquals(data.getAttribute(name), result.getAttribute(name));
+        }
+    }
+
+    @Test
+    public void testReadWithOtherCodecConfigured() throws Exception
+    {
+        SessionDataSerialization json = new SessionDataSerialization();
+        json.setCodecName("json");
+        SessionData data = new SessionData("1234", "_test", "0.0.0.0", 100, 200, 150, 30000);
+        data.setLastNode("node0");
+        data.setAttribute("name", "value");
+        byte[] bytes = RedisSessionData.serialize(json, data);
+
+        // The codec is recorded with the bytes, so a node configured with java can read them.
+        SessionData result = RedisSessionData.deserialize(new SessionDataSerialization(), bytes);
+        assertEquals("value", result.getAttribute("name"));
+    }
+}
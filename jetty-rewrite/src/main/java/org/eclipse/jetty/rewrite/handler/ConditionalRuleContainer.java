@@ -0,0 +1,69 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+/**
+ * Groups rules that apply only to requests matching a {@link RuleCondition}.
+ */
+public class ConditionalRuleContainer extends RuleContainer
+{
+    private RuleCondition _condition;
+
+    /**
+     * Set the condition of the requests that the rules within this container will apply to
+     *
+     * @param condition the condition expression, or null to apply the rules to all requests
+     * @throws IllegalArgumentException if the condition expression is invalid
+     * @see RuleCondition
+     */
+    public void setCondition(String condition)
+    {
+        _condition = condition == null ? null : new RuleCondition(condition);
+    }
+
+    /**
+     * Get the condition of the requests that the rules within this container will apply to
+     *
+     * @return the condition expression, or null if the rules apply to all requests
+     */
+    public String getCondition()
+    {
+        return _condition == null ? null : _condition.getExpression();
+    }
+
+    /**
+     * Process the contained rules if the request matches the condition of this container
+     *
+     * @param target target field to pass on to the contained rules
+     * @param request request object to pass on to the contained rules
+     * @param response response object to pass on to the contained rules
+     */
+    @Override
+    public String matchAndApply(String target, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (_condition == null || _condition.test(request))
+            return apply(target, request, response);
+        return null;
+    }
+
+    @Override
+    public String toString()
+    {
+        return super.toString() + "{" + getCondition() + "}";
+    }
+}
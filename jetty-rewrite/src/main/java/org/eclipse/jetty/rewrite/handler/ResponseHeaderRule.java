@@ -0,0 +1,149 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.regex.Pattern;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>Rewrites a header of responses.</p>
+ * <p>Without a regular expression, the header is set to the value, or added if
+ * {@link #setAdd(boolean) add} is true, or removed if the value is null.
+ * With a regular expression, the matches found in the existing values of the
+ * header are replaced with the value, that may contain group references
+ * such as {@code $1}, for example to rewrite the {@code Domain} attribute of
+ * {@code Set-Cookie} headers.</p>
+ */
+public class ResponseHeaderRule extends ResponseRule
+{
+    private String _name;
+    private String _value;
+    private Pattern _regex;
+    private boolean _add;
+
+    public ResponseHeaderRule()
+    {
+        this(null, null);
+    }
+
+    public ResponseHeaderRule(@Name("name") String name, @Name("value") String value)
+    {
+        setName(name);
+        setValue(value);
+    }
+
+    /**
+     * @return the header name
+     */
+    public String getName()
+    {
+        return _name;
+    }
+
+    /**
+     * @param name the header name
+     */
+    public void setName(String name)
+    {
+        _name = name;
+    }
+
+    /**
+     * @return the header value, or the replacement when a regular expression is set
+     */
+    public String getValue()
+    {
+        return _value;
+    }
+
+    /**
+     * @param value the header value, or the replacement when a regular expression is set
+     */
+    public void setValue(String value)
+    {
+        _value = value;
+    }
+
+    /**
+     * @return the regular expression to find in the header values, or null
+     */
+    public String getRegex()
+    {
+        return _regex == null ? null : _regex.pattern();
+    }
+
+    /**
+     * @param regex the regular expression to find in the header values, or null
+     */
+    public void setRegex(String regex)
+    {
+        _regex = regex == null ? null : Pattern.compile(regex);
+    }
+
+    /**
+     * @return the add flag value
+     */
+    public boolean isAdd()
+    {
+        return _add;
+    }
+
+    /**
+     * @param add If true, the header is added to the response, otherwise the header it is set on the response
+     */
+    public void setAdd(boolean add)
+    {
+        _add = add;
+    }
+
+    @Override
+    protected void applyResponse(HttpServletRequest request, HttpServletResponse response)
+    {
+        if (_regex != null)
+        {
+            List<String> values = new ArrayList<>(response.getHeaders(_name));
+            if (values.isEmpty())
+                return;
+            String replacement = _value == null ? "" : _value;
+            response.setHeader(_name, null);
+            for (String value : values)
+            {
+                response.addHeader(_name, _regex.matcher(value).replaceAll(replacement));
+            }
+        }
+        else if (_value == null)
+        {
+            response.setHeader(_name, null);
+        }
+        else if (_add)
+        {
+            response.addHeader(_name, _value);
+        }
+        else
+        {
+            response.setHeader(_name, _value);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return super.toString() + "[" + _name + (_regex == null ? "" : "~" + _regex) + "," + _value + "]";
+    }
+}
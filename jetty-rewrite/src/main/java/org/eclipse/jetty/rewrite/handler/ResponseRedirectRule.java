@@ -0,0 +1,102 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>Rewrites the {@code Location} and {@code Content-Location} headers of
+ * responses, typically when Jetty is a reverse proxy, so that the redirects
+ * produced by a backend server point to the proxy rather than to the backend.</p>
+ * <p>For example, with the backend prefix {@code http://backend:8080/app} and the
+ * proxy prefix {@code https://www.example.com/app}, the redirect to
+ * {@code http://backend:8080/app/login} is rewritten to
+ * {@code https://www.example.com/app/login}, while the redirects to other
+ * locations are left untouched.</p>
+ */
+public class ResponseRedirectRule extends ResponseRule
+{
+    private String _fromPrefix;
+    private String _toPrefix;
+
+    public ResponseRedirectRule()
+    {
+        this(null, null);
+    }
+
+    public ResponseRedirectRule(@Name("fromPrefix") String fromPrefix, @Name("toPrefix") String toPrefix)
+    {
+        _fromPrefix = fromPrefix;
+        _toPrefix = toPrefix;
+    }
+
+    /**
+     * @return the prefix of the locations to rewrite, typically the backend URI
+     */
+    public String getFromPrefix()
+    {
+        return _fromPrefix;
+    }
+
+    /**
+     * @param fromPrefix the prefix of the locations to rewrite, typically the backend URI
+     */
+    public void setFromPrefix(String fromPrefix)
+    {
+        _fromPrefix = fromPrefix;
+    }
+
+    /**
+     * @return the prefix that replaces {@link #getFromPrefix()}, typically the proxy URI
+     */
+    public String getToPrefix()
+    {
+        return _toPrefix;
+    }
+
+    /**
+     * @param toPrefix the prefix that replaces {@link #getFromPrefix()}, typically the proxy URI
+     */
+    public void setToPrefix(String toPrefix)
+    {
+        _toPrefix = toPrefix;
+    }
+
+    @Override
+    protected void applyResponse(HttpServletRequest request, HttpServletResponse response)
+    {
+        if (_fromPrefix == null || _toPrefix == null)
+            return;
+        rewrite(response, HttpHeader.LOCATION.asString());
+        rewrite(response, HttpHeader.CONTENT_LOCATION.asString());
+    }
+
+    private void rewrite(HttpServletResponse response, String header)
+    {
+        String location = response.getHeader(header);
+        if (location == null || !location.regionMatches(true, 0, _fromPrefix, 0, _fromPrefix.length()))
+            return;
+        response.setHeader(header, _toPrefix + location.substring(_fromPrefix.length()));
+    }
+
+    @Override
+    public String toString()
+    {
+        return super.toString() + "[" + _fromPrefix + "->" + _toPrefix + "]";
+    }
+}
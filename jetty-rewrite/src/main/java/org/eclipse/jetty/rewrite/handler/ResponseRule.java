@@ -0,0 +1,153 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.List;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.Callback;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An abstract rule that rewrites the response produced by the nested handlers,
+ * rather than the request.</p>
+ * <p>When the rule matches the request, it installs an {@link HttpOutput.Interceptor}
+ * that calls {@link #applyResponse(HttpServletRequest, HttpServletResponse)} just before
+ * the response is committed, when the status and headers can still be modified.</p>
+ * <p>Only responses written via the response output are rewritten, so for example the
+ * minimal error responses that the server generates when no error handler is available
+ * are not rewritten.
+ * Response rules are not {@link #setHandling(boolean) handling} nor
+ * {@link #setTerminating(boolean) terminating} by default;
+ * they may be grouped in a {@link ConditionalRuleContainer} to apply them only to
+ * some requests.</p>
+ */
+public abstract class ResponseRule extends Rule
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResponseRule.class);
+
+    public ResponseRule()
+    {
+        setHandling(false);
+        setTerminating(false);
+    }
+
+    @Override
+    public String matchAndApply(String target, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        if (response.isCommitted() || !matches(target, request))
+            return null;
+        ResponseRewriter.register(Request.getBaseRequest(request), this);
+        return target;
+    }
+
+    /**
+     * <p>Tests whether this rule applies to the response of the given request.</p>
+     * <p>The default implementation applies this rule to all requests.</p>
+     *
+     * @param target the target of the request
+     * @param request the request
+     * @return whether this rule applies to the response of the given request
+     */
+    protected boolean matches(String target, HttpServletRequest request)
+    {
+        return true;
+    }
+
+    /**
+     * <p>Rewrites the response just before it is committed.</p>
+     *
+     * @param request the request
+     * @param response the response to rewrite
+     */
+    protected abstract void applyResponse(HttpServletRequest request, HttpServletResponse response);
+
+    /**
+     * <p>Applies the response rules registered for a request before the response is committed.</p>
+     */
+    private static class ResponseRewriter implements HttpOutput.Interceptor
+    {
+        private final List<ResponseRule> _rules = new ArrayList<>();
+        private final Request _request;
+        private final HttpOutput.Interceptor _next;
+        private boolean _applied;
+
+        private ResponseRewriter(Request request, HttpOutput.Interceptor next)
+        {
+            _request = request;
+            _next = next;
+        }
+
+        private static void register(Request baseRequest, ResponseRule rule)
+        {
+            HttpOutput out = baseRequest.getResponse().getHttpOutput();
+            ResponseRewriter rewriter = null;
+            for (HttpOutput.Interceptor interceptor = out.getInterceptor(); interceptor != null; interceptor = interceptor.getNextInterceptor())
+            {
+                if (interceptor instanceof ResponseRewriter)
+                {
+                    rewriter = (ResponseRewriter)interceptor;
+                    break;
+                }
+            }
+            if (rewriter == null)
+            {
+                rewriter = new ResponseRewriter(baseRequest, out.getInterceptor());
+                out.setInterceptor(rewriter);
+            }
+            // Rules are applied again for asynchronous dispatches.
+            if (!rewriter._rules.contains(rule))
+                rewriter._rules.add(rule);
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            if (!_applied)
+            {
+                _applied = true;
+                Response response = _request.getResponse();
+                try
+                {
+                    for (ResponseRule rule : _rules)
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("applying {} to {}", rule, response);
+                        rule.applyResponse(_request, response);
+                    }
+                }
+                catch (Throwable x)
+                {
+                    callback.failed(x);
+                    return;
+                }
+            }
+            _next.write(content, last, callback);
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+    }
+}
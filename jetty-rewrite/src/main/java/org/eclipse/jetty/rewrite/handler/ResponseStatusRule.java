@@ -0,0 +1,85 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>Rewrites the status code of responses, for example to hide the
+ * {@code 500} responses of an application behind {@code 503} responses.</p>
+ */
+public class ResponseStatusRule extends ResponseRule
+{
+    private int _fromStatus = -1;
+    private int _toStatus;
+
+    public ResponseStatusRule()
+    {
+        this(-1, 0);
+    }
+
+    public ResponseStatusRule(@Name("fromStatus") int fromStatus, @Name("toStatus") int toStatus)
+    {
+        _fromStatus = fromStatus;
+        _toStatus = toStatus;
+    }
+
+    /**
+     * @return the status code of the responses to rewrite, or -1 to rewrite any status code
+     */
+    public int getFromStatus()
+    {
+        return _fromStatus;
+    }
+
+    /**
+     * @param fromStatus the status code of the responses to rewrite, or -1 to rewrite any status code
+     */
+    public void setFromStatus(int fromStatus)
+    {
+        _fromStatus = fromStatus;
+    }
+
+    /**
+     * @return the status code to rewrite responses to
+     */
+    public int getToStatus()
+    {
+        return _toStatus;
+    }
+
+    /**
+     * @param toStatus the status code to rewrite responses to
+     */
+    public void setToStatus(int toStatus)
+    {
+        _toStatus = toStatus;
+    }
+
+    @Override
+    protected void applyResponse(HttpServletRequest request, HttpServletResponse response)
+    {
+        if (_toStatus > 0 && (_fromStatus < 0 || _fromStatus == response.getStatus()))
+            response.setStatus(_toStatus);
+    }
+
+    @Override
+    public String toString()
+    {
+        return super.toString() + "[" + _fromStatus + "->" + _toStatus + "]";
+    }
+}
@@ -49,6 +49,10 @@ import org.eclipse.jetty.server.handler.HandlerWrapper;
  * <li> {@link MsieSslRule} - disables the keep alive on SSL for IE5 and IE6. </li>
  * <li> {@link ForwardedSchemeHeaderRule} - set the scheme according to the headers present. </li>
  * <li> {@link VirtualHostRuleContainer} - checks whether the request matches one of a set of virtual host names.</li>
+ * <li> {@link ConditionalRuleContainer} - applies its rules only to the requests that match a {@link RuleCondition}.</li>
+ * <li> {@link ResponseStatusRule} - rewrites the status code of the response.</li>
+ * <li> {@link ResponseHeaderRule} - sets, removes or rewrites a header of the response.</li>
+ * <li> {@link ResponseRedirectRule} - rewrites the redirect locations of the response, typically for reverse proxies.</li>
  * </ul>
  *
  *
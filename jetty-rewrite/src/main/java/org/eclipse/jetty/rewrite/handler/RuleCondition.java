@@ -0,0 +1,355 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import java.nio.charset.StandardCharsets;
+import java.util.function.Function;
+import java.util.function.Predicate;
+import java.util.regex.Pattern;
+import java.util.regex.PatternSyntaxException;
+import javax.net.ssl.SSLSession;
+import javax.servlet.http.HttpServletRequest;
+
+import org.eclipse.jetty.server.SecureRequestCustomizer;
+import org.eclipse.jetty.util.MultiMap;
+import org.eclipse.jetty.util.UrlEncoded;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A condition on requests, written in a small expression language,
+ * so that rules can be applied to requests without writing custom rules.</p>
+ * <p>An expression is made of terms combined with {@code &&}, {@code ||}, {@code !}
+ * and parentheses, where a term is an operand optionally compared to a value:</p>
+ * <ul>
+ * <li>{@code operand} is true if the operand has a value</li>
+ * <li>{@code operand == value} and {@code operand != value} compare the operand value to the given value</li>
+ * <li>{@code operand =~ regex} and {@code operand !~ regex} test whether the regular expression
+ * finds a match in the operand value</li>
+ * </ul>
+ * <p>The operands are:</p>
+ * <ul>
+ * <li>{@code method}, {@code scheme}, {@code host}, {@code path}, {@code query} and {@code remote},
+ * for the request method, scheme, server name, URI path, query string and remote address</li>
+ * <li>{@code secure}, that has a value only if the request is secure</li>
+ * <li>{@code header(name)}, {@code param(name)} and {@code attribute(name)} for the request header,
+ * query parameter and request attribute with the given name</li>
+ * <li>{@code tls.protocol}, {@code tls.cipher} and {@code tls.keySize} for the TLS attributes
+ * of requests customized by {@link SecureRequestCustomizer}</li>
+ * </ul>
+ * <p>Values are either unquoted words or strings quoted with {@code "} or {@code '},
+ * in which the backslash escapes the next character, for example:</p>
+ * <pre>
+ * method == POST &amp;&amp; header(Content-Type) =~ "^application/json" &amp;&amp; !param(debug)
+ * secure &amp;&amp; (tls.protocol == TLSv1.3 || tls.protocol == TLSv1.2)
+ * </pre>
+ *
+ * @see ConditionalRuleContainer
+ */
+public class RuleCondition implements Predicate<HttpServletRequest>
+{
+    private static final String SSL_SESSION_ATTRIBUTE = "org.eclipse.jetty.servlet.request.ssl_session";
+
+    private final String _expression;
+    private final Predicate<HttpServletRequest> _predicate;
+
+    /**
+     * @param expression the condition expression
+     * @throws IllegalArgumentException if the expression is invalid
+     */
+    public RuleCondition(@Name("expression") String expression)
+    {
+        _expression = expression;
+        _predicate = new Parser(expression).parse();
+    }
+
+    /**
+     * @return the condition expression
+     */
+    public String getExpression()
+    {
+        return _expression;
+    }
+
+    @Override
+    public boolean test(HttpServletRequest request)
+    {
+        return _predicate.test(request);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s[%s]", getClass().getSimpleName(), _expression);
+    }
+
+    private static String getQueryParameter(HttpServletRequest request, String name)
+    {
+        String query = request.getQueryString();
+        if (query == null)
+            return null;
+        try
+        {
+            MultiMap<String> parameters = new MultiMap<>();
+            UrlEncoded.decodeTo(query, parameters, StandardCharsets.UTF_8);
+            return parameters.getValue(name, 0);
+        }
+        catch (IllegalArgumentException x)
+        {
+            // Badly encoded query string.
+            return null;
+        }
+    }
+
+    private static String getTLSProtocol(HttpServletRequest request)
+    {
+        Object session = request.getAttribute(SSL_SESSION_ATTRIBUTE);
+        return session instanceof SSLSession ? ((SSLSession)session).getProtocol() : null;
+    }
+
+    private static String toString(Object value)
+    {
+        return value == null ? null : value.toString();
+    }
+
+    /**
+     * <p>A recursive descent parser of condition expressions.</p>
+     */
+    private static class Parser
+    {
+        private final String _input;
+        private int _index;
+
+        private Parser(String input)
+        {
+            _input = input == null ? "" : input;
+        }
+
+        private Predicate<HttpServletRequest> parse()
+        {
+            Predicate<HttpServletRequest> predicate = parseOr();
+            skipWhitespace();
+            if (_index < _input.length())
+                throw error("Unexpected '" + _input.charAt(_index) + "'");
+            return predicate;
+        }
+
+        private Predicate<HttpServletRequest> parseOr()
+        {
+            Predicate<HttpServletRequest> predicate = parseAnd();
+            while (consume("||"))
+            {
+                predicate = predicate.or(parseAnd());
+            }
+            return predicate;
+        }
+
+        private Predicate<HttpServletRequest> parseAnd()
+        {
+            Predicate<HttpServletRequest> predicate = parseUnary();
+            while (consume("&&"))
+            {
+                predicate = predicate.and(parseUnary());
+            }
+            return predicate;
+        }
+
+        private Predicate<HttpServletRequest> parseUnary()
+        {
+            if (consume("!"))
+                return parseUnary().negate();
+            if (consume("("))
+            {
+                Predicate<HttpServletRequest> predicate = parseOr();
+                expect(")");
+                return predicate;
+            }
+            return parseTerm();
+        }
+
+        private Predicate<HttpServletRequest> parseTerm()
+        {
+            Function<HttpServletRequest, String> operand = parseOperand();
+            if (consume("=="))
+            {
+                String value = parseValue();
+                return request -> value.equals(operand.apply(request));
+            }
+            if (consume("!="))
+            {
+                String value = parseValue();
+                return request -> !value.equals(operand.apply(request));
+            }
+            if (consume("=~"))
+            {
+                Pattern pattern = parsePattern();
+                return request -> matches(pattern, operand.apply(request));
+            }
+            if (consume("!~"))
+            {
+                Pattern pattern = parsePattern();
+                return request -> !matches(pattern, operand.apply(request));
+            }
+            return request -> operand.apply(request) != null;
+        }
+
+        private static boolean matches(Pattern pattern, String value)
+        {
+            return value != null && pattern.matcher(value).find();
+        }
+
+        private Function<HttpServletRequest, String> parseOperand()
+        {
+            skipWhitespace();
+            int start = _index;
+            while (_index < _input.length())
+            {
+                char c = _input.charAt(_index);
+                if (!Character.isLetterOrDigit(c) && c != '.' && c != '_')
+                    break;
+                ++_index;
+            }
+            String name = _input.substring(start, _index);
+            switch (name)
+            {
+                case "method":
+                    return HttpServletRequest::getMethod;
+                case "scheme":
+                    return HttpServletRequest::getScheme;
+                case "host":
+                    return HttpServletRequest::getServerName;
+                case "path":
+                    return HttpServletRequest::getRequestURI;
+                case "query":
+                    return HttpServletRequest::getQueryString;
+                case "remote":
+                    return HttpServletRequest::getRemoteAddr;
+                case "secure":
+                    return request -> request.isSecure() ? "true" : null;
+                case "header":
+                {
+                    String header = parseArgument();
+                    return request -> request.getHeader(header);
+                }
+                case "param":
+                {
+                    String param = parseArgument();
+                    return request -> getQueryParameter(request, param);
+                }
+                case "attribute":
+                {
+                    String attribute = parseArgument();
+                    return request -> RuleCondition.toString(request.getAttribute(attribute));
+                }
+                case "tls.protocol":
+                    return RuleCondition::getTLSProtocol;
+                case "tls.cipher":
+                    return request -> RuleCondition.toString(request.getAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_CIPHER_SUITE));
+                case "tls.keySize":
+                    return request -> RuleCondition.toString(request.getAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_KEY_SIZE));
+                default:
+                    _index = start;
+                    throw error(name.isEmpty() ? "Missing operand" : "Unknown operand '" + name + "'");
+            }
+        }
+
+        private String parseArgument()
+        {
+            expect("(");
+            String argument = parseValue();
+            expect(")");
+            return argument;
+        }
+
+        private Pattern parsePattern()
+        {
+            int start = _index;
+            String regex = parseValue();
+            try
+            {
+                return Pattern.compile(regex);
+            }
+            catch (PatternSyntaxException x)
+            {
+                _index = start;
+                throw error("Invalid regular expression '" + regex + "'");
+            }
+        }
+
+        private String parseValue()
+        {
+            skipWhitespace();
+            if (_index == _input.length())
+                throw error("Missing value");
+
+            char quote = _input.charAt(_index);
+            if (quote == '"' || quote == '\'')
+            {
+                StringBuilder builder = new StringBuilder();
+                ++_index;
+                while (_index < _input.length())
+                {
+                    char c = _input.charAt(_index++);
+                    if (c == quote)
+                        return builder.toString();
+                    if (c == '\\' && _index < _input.length())
+                        c = _input.charAt(_index++);
+                    builder.append(c);
+                }
+                throw error("Unterminated string");
+            }
+
+            int start = _index;
+            while (_index < _input.length())
+            {
+                char c = _input.charAt(_index);
+                if (Character.isWhitespace(c) || "()!=~&|".indexOf(c) >= 0)
+                    break;
+                ++_index;
+            }
+            if (start == _index)
+                throw error("Missing value");
+            return _input.substring(start, _index);
+        }
+
+        private boolean consume(String token)
+        {
+            skipWhitespace();
+            if (_input.startsWith(token, _index))
+            {
+                _index += token.length();
+                return true;
+            }
+            return false;
+        }
+
+        private void expect(String token)
+        {
+            if (!consume(token))
+                throw error("Expected '" + token + "'");
+        }
+
+        private void skipWhitespace()
+        {
+            while (_index < _input.length() && Character.isWhitespace(_input.charAt(_index)))
+            {
+                ++_index;
+            }
+        }
+
+        private IllegalArgumentException error(String message)
+        {
+            return new IllegalArgumentException(message + " at index " + _index + " in condition: " + _input);
+        }
+    }
+}
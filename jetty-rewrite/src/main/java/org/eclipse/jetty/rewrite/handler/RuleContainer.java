@@ -18,6 +18,7 @@ import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.http.UriCanonicalizer;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.util.ArrayUtil;
 import org.eclipse.jetty.util.URIUtil;
@@ -160,6 +161,19 @@ public class RuleContainer extends Rule implements Dumpable
             String applied = rule.matchAndApply(target, request, response);
             if (applied != null)
             {
+                // Ugly hack, we should just pass baseRequest into the API from RewriteHandler itself.
+                Request baseRequest = Request.getBaseRequest(request);
+
+                // Rules may produce paths that are not canonical, for example from regex groups,
+                // so they are canonicalized like the request path was.
+                String encoded = URIUtil.encodePath(applied);
+                UriCanonicalizer.CanonicalPath canonical = applied.startsWith("/") ? baseRequest.canonicalizePath(encoded) : null;
+                if (canonical != null)
+                {
+                    encoded = canonical.getPath();
+                    applied = canonical.getDecodedPath();
+                }
+
                 LOG.debug("applied {}", rule);
                 LOG.debug("rewrote {} to {}", target, applied);
                 if (!originalSet)
@@ -172,12 +186,8 @@ public class RuleContainer extends Rule implements Dumpable
                         request.setAttribute(_originalQueryStringAttribute, query);
                 }
 
-                // Ugly hack, we should just pass baseRequest into the API from RewriteHandler itself.
-                Request baseRequest = Request.getBaseRequest(request);
-
                 if (_rewriteRequestURI)
                 {
-                    String encoded = URIUtil.encodePath(applied);
                     if (rule instanceof Rule.ApplyURI)
                         ((Rule.ApplyURI)rule).applyURI(baseRequest, baseRequest.getRequestURI(), encoded);
                     else
@@ -0,0 +1,141 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class ResponseRuleTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private RewriteHandler _rewriteHandler;
+
+    @BeforeEach
+    public void init()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _rewriteHandler = new RewriteHandler();
+        _rewriteHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if (target.startsWith("/redirect"))
+                {
+                    response.sendRedirect("http://backend:8080/app/login");
+                    return;
+                }
+                response.setStatus(target.startsWith("/fail") ? 500 : 200);
+                response.setHeader("Server-Id", "backend-1");
+                response.addHeader("Set-Cookie", "a=1; Domain=backend");
+                response.addHeader("Set-Cookie", "b=2; Domain=backend");
+                response.getWriter().print("content");
+            }
+        });
+        _server.setHandler(_rewriteHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testResponseStatusRule() throws Exception
+    {
+        _rewriteHandler.addRule(new ResponseStatusRule(500, 503));
+        _server.start();
+
+        HttpTester.Response response = get("/fail");
+        assertThat(response.getStatus(), is(503));
+        assertThat(response.getContent(), is("content"));
+        assertThat(get("/ok").getStatus(), is(200));
+    }
+
+    @Test
+    public void testResponseHeaderRule() throws Exception
+    {
+        _rewriteHandler.addRule(new ResponseHeaderRule("Server-Id", null));
+        ResponseHeaderRule cookieRule = new ResponseHeaderRule("Set-Cookie", "Domain=example.com");
+        cookieRule.setRegex("Domain=backend");
+        _rewriteHandler.addRule(cookieRule);
+        _rewriteHandler.addRule(new ResponseHeaderRule("X-Frame-Options", "DENY"));
+        _server.start();
+
+        HttpTester.Response response = get("/ok");
+        assertThat(response.getStatus(), is(200));
+        assertThat(response.get("Server-Id"), nullValue());
+        assertThat(response.get("X-Frame-Options"), is("DENY"));
+        assertThat(response.getValuesList(HttpHeader.SET_COOKIE), contains("a=1; Domain=example.com", "b=2; Domain=example.com"));
+    }
+
+    @Test
+    public void testResponseRedirectRule() throws Exception
+    {
+        _rewriteHandler.addRule(new ResponseRedirectRule("http://backend:8080/app", "https://www.example.com/app"));
+        _server.start();
+
+        HttpTester.Response response = get("/redirect");
+        assertThat(response.getStatus(), is(302));
+        assertThat(response.get(HttpHeader.LOCATION), is("https://www.example.com/app/login"));
+    }
+
+    @Test
+    public void testConditionalRuleContainer() throws Exception
+    {
+        ConditionalRuleContainer container = new ConditionalRuleContainer();
+        container.setCondition("path =~ ^/fail && !param(debug)");
+        container.addRule(new ResponseStatusRule(500, 503));
+        _rewriteHandler.addRule(container);
+        _server.start();
+
+        assertThat(get("/fail").getStatus(), is(503));
+        assertThat(get("/fail?debug=true").getStatus(), is(500));
+    }
+
+    @Test
+    public void testInvalidCondition()
+    {
+        ConditionalRuleContainer container = new ConditionalRuleContainer();
+        assertThrows(IllegalArgumentException.class, () -> container.setCondition("path =~"));
+    }
+}
@@ -18,13 +18,17 @@ import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.http.BadMessageException;
 import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.http.UriCanonicalizer;
+import org.eclipse.jetty.server.HttpConnectionFactory;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.handler.AbstractHandler;
 import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
 
 import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
 public class RewriteHandlerTest extends AbstractRuleTestCase
@@ -192,4 +196,27 @@ public class RewriteHandlerTest extends AbstractRuleTestCase
         assertEquals("/x%20y/zzz", _request.getAttribute("URI"));
         assertEquals("/xxx/x y", _request.getAttribute("info"));
     }
+
+    @Test
+    public void testCanonicalizedRewrite() throws Exception
+    {
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        _connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().setUriCanonicalizer(canonicalizer);
+        _response.setStatus(200);
+        _request.setHandled(false);
+        _handler.setRewriteRequestURI(true);
+        _handler.setRewritePathInfo(false);
+        _request.setHttpURI(HttpURI.build(_request.getHttpURI(), "/xxx/a/%2E%2E/b"));
+        _request.setContext(_request.getContext(), "/xxx/a/../b");
+        _handler.handle("/xxx/a/../b", _request, _request, _response);
+        assertEquals(201, _response.getStatus());
+        assertEquals("/b/zzz", _request.getAttribute("target"));
+        assertEquals("/b/zzz", _request.getAttribute("URI"));
+
+        canonicalizer.setAllowDotSegments(false);
+        _request.setHandled(false);
+        _request.setHttpURI(HttpURI.build(_request.getHttpURI(), "/xxx/a/%2E%2E/b"));
+        _request.setContext(_request.getContext(), "/xxx/a/../b");
+        assertThrows(BadMessageException.class, () -> _handler.handle("/xxx/a/../b", _request, _request, _response));
+    }
 }
@@ -0,0 +1,113 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.rewrite.handler;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.server.SecureRequestCustomizer;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class RuleConditionTest extends AbstractRuleTestCase
+{
+    @BeforeEach
+    public void init() throws Exception
+    {
+        start(false);
+        _request.setMethod("POST");
+        _request.setHttpURI(HttpURI.build(_request.getHttpURI(), "/api/items", null, "debug=1&name=a%20b"));
+        _request.setHttpFields(HttpFields.build(_request.getHttpFields())
+            .put("Content-Type", "application/json; charset=utf-8")
+            .put("X-Tenant", "acme"));
+    }
+
+    private boolean test(String expression)
+    {
+        return new RuleCondition(expression).test(_request);
+    }
+
+    @Test
+    public void testComparisons()
+    {
+        assertTrue(test("method == POST"));
+        assertFalse(test("method != POST"));
+        assertTrue(test("path =~ ^/api/"));
+        assertFalse(test("path !~ ^/api/"));
+        assertTrue(test("header(Content-Type) =~ \"^application/json\""));
+        assertTrue(test("header(X-Tenant) == 'acme'"));
+        assertFalse(test("header(X-Missing) == acme"));
+        assertTrue(test("param(name) == \"a b\""));
+        assertTrue(test("query =~ debug"));
+    }
+
+    @Test
+    public void testExistence()
+    {
+        assertTrue(test("param(debug)"));
+        assertFalse(test("param(verbose)"));
+        assertTrue(test("header(X-Tenant)"));
+        assertFalse(test("secure"));
+        assertFalse(test("tls.protocol"));
+
+        _request.setAttribute("custom", "value");
+        assertTrue(test("attribute(custom)"));
+    }
+
+    @Test
+    public void testBooleanOperators()
+    {
+        assertTrue(test("method == GET || method == POST"));
+        assertFalse(test("method == POST && param(verbose)"));
+        assertTrue(test("method == POST && !param(verbose)"));
+        assertTrue(test("!(method == GET || path == /)"));
+        // && binds more tightly than ||.
+        assertTrue(test("method == POST || method == GET && param(verbose)"));
+        assertFalse(test("(method == POST || method == GET) && param(verbose)"));
+    }
+
+    @Test
+    public void testSecure()
+    {
+        _request.setSecure(true);
+        _request.setAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_CIPHER_SUITE, "TLS_AES_128_GCM_SHA256");
+        _request.setAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_KEY_SIZE, 128);
+
+        assertTrue(test("secure"));
+        assertTrue(test("secure && tls.cipher =~ GCM"));
+        assertTrue(test("tls.keySize == 128"));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {
+        "",
+        "method ==",
+        "method == POST &&",
+        "(method == POST",
+        "method == POST)",
+        "unknown == POST",
+        "header() == x",
+        "path =~ \"[\"",
+        "method == \"POST"
+    })
+    public void testInvalidExpression(String expression)
+    {
+        assertThrows(IllegalArgumentException.class, () -> new RuleCondition(expression));
+    }
+}
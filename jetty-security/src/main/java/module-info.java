@@ -21,6 +21,8 @@ module org.eclipse.jetty.security
     requires static java.security.jgss;
     // Only required if using JDBCLoginService.
     requires static java.sql;
+    // Only required if using JwtAuthenticator or RulesAuthorizationPolicy.
+    requires static org.eclipse.jetty.util.ajax;
 
     exports org.eclipse.jetty.security;
     exports org.eclipse.jetty.security.authentication;
@@ -0,0 +1,61 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security;
+
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.UserIdentity;
+
+/**
+ * <p>An authorization policy consulted by the {@link SecurityHandler} for each request,
+ * in addition to the role based {@link ConstraintMapping}s.</p>
+ * <p>The policy can base its decision on any property of the request, such as its
+ * path, method and attributes, on the authenticated identity, or on the current time.</p>
+ * <p>A request that is not authenticated is first authorized with a {@code null}
+ * user identity; if the policy denies it, the request is authenticated, if possible,
+ * and authorized again with the authenticated user identity.</p>
+ *
+ * @see SecurityHandler#setAuthorizationPolicy(AuthorizationPolicy)
+ * @see RulesAuthorizationPolicy
+ */
+@FunctionalInterface
+public interface AuthorizationPolicy
+{
+    /**
+     * @param request the request to authorize
+     * @param pathInContext the path of the request within the context
+     * @param userIdentity the authenticated user identity, or null if the request is not authenticated
+     * @return the authorization decision, never null
+     */
+    Decision authorize(Request request, String pathInContext, UserIdentity userIdentity);
+
+    /**
+     * The decision of an {@link AuthorizationPolicy}.
+     */
+    enum Decision
+    {
+        /**
+         * The request is permitted.
+         */
+        PERMIT,
+        /**
+         * The request is denied with a 403 response.
+         */
+        DENY,
+        /**
+         * The policy does not apply to the request, which is permitted
+         * if the {@link ConstraintMapping}s permit it.
+         */
+        NOT_APPLICABLE
+    }
+}
@@ -22,6 +22,7 @@ import org.eclipse.jetty.security.authentication.ClientCertAuthenticator;
 import org.eclipse.jetty.security.authentication.ConfigurableSpnegoAuthenticator;
 import org.eclipse.jetty.security.authentication.DigestAuthenticator;
 import org.eclipse.jetty.security.authentication.FormAuthenticator;
+import org.eclipse.jetty.security.authentication.JwtAuthenticator;
 import org.eclipse.jetty.security.authentication.SslClientCertAuthenticator;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.util.security.Constraint;
@@ -37,6 +38,7 @@ import org.slf4j.LoggerFactory;
  * <li>{@link org.eclipse.jetty.security.authentication.FormAuthenticator}</li>
  * <li>{@link org.eclipse.jetty.security.authentication.ClientCertAuthenticator}</li>
  * <li>{@link org.eclipse.jetty.security.authentication.SslClientCertAuthenticator}</li>
+ * <li>{@link org.eclipse.jetty.security.authentication.JwtAuthenticator}</li>
  * </ul>
  * All authenticators derived from {@link org.eclipse.jetty.security.authentication.LoginAuthenticator} are
  * wrapped with a {@link org.eclipse.jetty.security.authentication.DeferredAuthentication}
@@ -72,6 +74,8 @@ public class DefaultAuthenticatorFactory implements Authenticator.Factory
             authenticator = new ConfigurableSpnegoAuthenticator();
         else if (Constraint.__NEGOTIATE_AUTH.equalsIgnoreCase(auth)) // see Bug #377076
             authenticator = new ConfigurableSpnegoAuthenticator(Constraint.__NEGOTIATE_AUTH);
+        else if (Constraint.__JWT_AUTH.equalsIgnoreCase(auth))
+            authenticator = new JwtAuthenticator();
         if (Constraint.__CERT_AUTH.equalsIgnoreCase(auth) || Constraint.__CERT_AUTH2.equalsIgnoreCase(auth))
         {
             Collection<SslContextFactory> sslContextFactories = server.getBeans(SslContextFactory.class);
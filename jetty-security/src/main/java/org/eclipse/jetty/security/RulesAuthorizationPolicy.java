@@ -0,0 +1,600 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.time.Clock;
+import java.time.DayOfWeek;
+import java.time.LocalTime;
+import java.time.ZoneId;
+import java.time.ZonedDateTime;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.EnumSet;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+
+import org.eclipse.jetty.http.pathmap.PathSpec;
+import org.eclipse.jetty.http.pathmap.UriTemplatePathSpec;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.UserIdentity;
+import org.eclipse.jetty.util.PathWatcher;
+import org.eclipse.jetty.util.PathWatcher.PathWatchEvent;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.ajax.YamlParser;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link AuthorizationPolicy} that evaluates an ordered list of rules read from a JSON policy file,
+ * or from a YAML policy file if its name ends with {@code .yaml} or {@code .yml}; YAML files are parsed
+ * with {@link YamlParser}, which supports only the subset of YAML described there.</p>
+ * <p>The first rule whose conditions all match the request determines the decision;
+ * if no rule matches, the default decision applies. For example:</p>
+ * <pre>
+ * {
+ *   "default": "DENY",
+ *   "rules": [
+ *     {"name": "public", "path": "/public/*", "effect": "PERMIT"},
+ *     {"name": "owner", "path": "/users/{name}/profile", "methods": ["GET", "PUT"], "user": "{name}", "effect": "PERMIT"},
+ *     {"name": "admin", "path": "/admin/*", "roles": ["admin"], "time": {"days": ["MONDAY", "FRIDAY"], "from": "08:00", "to": "18:00", "zone": "UTC"}, "effect": "PERMIT"},
+ *     {"name": "internal", "attributes": {"network": "internal"}, "authenticated": true, "effect": "PERMIT"}
+ *   ]
+ * }
+ * </pre>
+ * <p>The same policy in YAML, where values starting with <code>{</code> must be quoted:</p>
+ * <pre>
+ * default: DENY
+ * rules:
+ *   - {name: public, path: /public/*, effect: PERMIT}
+ *   - name: owner
+ *     path: /users/{name}/profile
+ *     methods: [GET, PUT]
+ *     user: "{name}"
+ *     effect: PERMIT
+ * </pre>
+ * <p>The {@code default} member is one of {@code PERMIT}, {@code DENY} or {@code NOT_APPLICABLE}
+ * (the default). Each rule has an {@code effect}, either {@code PERMIT} or {@code DENY},
+ * and any of the following conditions:</p>
+ * <ul>
+ * <li>{@code path}: a servlet path spec, a regex path spec starting with {@code ^}, or a URI
+ * template such as {@code /users/{name}}, whose variables can be referenced by the
+ * {@code user} and {@code attributes} conditions;</li>
+ * <li>{@code methods}: the HTTP methods of the request;</li>
+ * <li>{@code authenticated}: whether the request must be authenticated or not;</li>
+ * <li>{@code roles}: the request must be authenticated with any of these roles;</li>
+ * <li>{@code user}: the request must be authenticated with this user name;</li>
+ * <li>{@code attributes}: the string values of the request attributes with the given names;</li>
+ * <li>{@code time}: the days of the week and the time of the day, from inclusive to exclusive,
+ * in the given zone or in the zone of the {@link #setClock(Clock) clock}.</li>
+ * </ul>
+ * <p>When {@link #setHotReload(boolean) hot reload} is enabled, the policy file is reloaded
+ * when it changes; if the new file is invalid, the previous rules are retained.</p>
+ * <p>Decisions are logged by the {@code org.eclipse.jetty.security.RulesAuthorizationPolicy.audit}
+ * logger: denials at {@code INFO} level, other decisions at {@code DEBUG} level.</p>
+ */
+@ManagedObject("Rule based authorization policy")
+public class RulesAuthorizationPolicy extends ContainerLifeCycle implements AuthorizationPolicy, PathWatcher.Listener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RulesAuthorizationPolicy.class);
+    private static final Logger AUDIT = LoggerFactory.getLogger(RulesAuthorizationPolicy.class.getName() + ".audit");
+
+    private Path _configPath;
+    private boolean _hotReload;
+    private Clock _clock = Clock.systemDefaultZone();
+    private PathWatcher _pathWatcher;
+    private volatile Policy _policy = new Policy(Decision.NOT_APPLICABLE, List.of());
+
+    /**
+     * @return the policy file path as a string
+     */
+    public String getConfig()
+    {
+        return _configPath == null ? null : _configPath.toString();
+    }
+
+    /**
+     * @param config the policy file path as a string
+     */
+    public void setConfig(String config)
+    {
+        setConfigPath(config == null ? null : Path.of(config));
+    }
+
+    /**
+     * @return the policy file path
+     */
+    public Path getConfigPath()
+    {
+        return _configPath;
+    }
+
+    /**
+     * @param configPath the policy file path
+     */
+    public void setConfigPath(Path configPath)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        _configPath = configPath;
+    }
+
+    /**
+     * @return whether the policy file is reloaded when it changes
+     */
+    @ManagedAttribute("Whether the policy file is reloaded when it changes")
+    public boolean isHotReload()
+    {
+        return _hotReload;
+    }
+
+    /**
+     * @param hotReload whether the policy file is reloaded when it changes
+     */
+    public void setHotReload(boolean hotReload)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        _hotReload = hotReload;
+    }
+
+    /**
+     * @return the clock used to evaluate the time conditions
+     */
+    public Clock getClock()
+    {
+        return _clock;
+    }
+
+    /**
+     * @param clock the clock used to evaluate the time conditions
+     */
+    public void setClock(Clock clock)
+    {
+        _clock = Objects.requireNonNull(clock);
+    }
+
+    /**
+     * @return the number of rules of the policy
+     */
+    @ManagedAttribute("The number of rules of the policy")
+    public int getRuleCount()
+    {
+        return _policy.rules.size();
+    }
+
+    /**
+     * @return the decision when no rule matches
+     */
+    @ManagedAttribute("The decision when no rule matches")
+    public Decision getDefaultDecision()
+    {
+        return _policy.defaultDecision;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        super.doStart();
+        if (_configPath != null)
+        {
+            reload();
+            if (isHotReload())
+            {
+                _pathWatcher = new PathWatcher();
+                _pathWatcher.watch(_configPath);
+                _pathWatcher.addListener(this);
+                _pathWatcher.setNotifyExistingOnStart(false);
+                _pathWatcher.start();
+            }
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        if (_pathWatcher != null)
+            _pathWatcher.stop();
+        _pathWatcher = null;
+        super.doStop();
+    }
+
+    /**
+     * <p>Reloads the policy file.</p>
+     *
+     * @throws IOException if the policy file cannot be read
+     * @throws IllegalArgumentException if the policy file is invalid
+     */
+    @ManagedOperation(value = "Reloads the policy file", impact = "ACTION")
+    public void reload() throws IOException
+    {
+        if (_configPath == null)
+            return;
+        String content = Files.readString(_configPath, StandardCharsets.UTF_8);
+        String fileName = _configPath.getFileName().toString().toLowerCase(Locale.ENGLISH);
+        Policy policy = Policy.parse(content, fileName.endsWith(".yaml") || fileName.endsWith(".yml"));
+        _policy = policy;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Loaded {} rules from {}", policy.rules.size(), _configPath);
+    }
+
+    @Override
+    public void onPathWatchEvent(PathWatchEvent event)
+    {
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Path watch event: {}", event.getType());
+            reload();
+        }
+        catch (IOException | IllegalArgumentException x)
+        {
+            LOG.warn("Unable to reload authorization policy {}, retaining the previous rules", _configPath, x);
+        }
+    }
+
+    @Override
+    public Decision authorize(Request request, String pathInContext, UserIdentity userIdentity)
+    {
+        Policy policy = _policy;
+        for (Rule rule : policy.rules)
+        {
+            if (rule.matches(request, pathInContext, userIdentity, _clock))
+            {
+                audit(rule.effect, rule.name, request, pathInContext, userIdentity);
+                return rule.effect;
+            }
+        }
+        audit(policy.defaultDecision, null, request, pathInContext, userIdentity);
+        return policy.defaultDecision;
+    }
+
+    private void audit(Decision decision, String rule, Request request, String pathInContext, UserIdentity userIdentity)
+    {
+        boolean deny = decision == Decision.DENY;
+        if (deny ? AUDIT.isInfoEnabled() : AUDIT.isDebugEnabled())
+        {
+            String user = userIdentity == null ? null : userIdentity.getUserPrincipal().getName();
+            String message = String.format("%s %s %s from %s user=%s rule=%s",
+                decision, request.getMethod(), pathInContext, request.getRemoteAddr(), user, rule);
+            if (deny)
+                AUDIT.info(message);
+            else
+                AUDIT.debug(message);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[config=%s,rules=%d]", getClass().getSimpleName(), hashCode(), _configPath, getRuleCount());
+    }
+
+    private static class Policy
+    {
+        private final Decision defaultDecision;
+        private final List<Rule> rules;
+
+        private Policy(Decision defaultDecision, List<Rule> rules)
+        {
+            this.defaultDecision = defaultDecision;
+            this.rules = rules;
+        }
+
+        private static Policy parse(String content, boolean yaml)
+        {
+            Object parsed;
+            try
+            {
+                parsed = yaml ? YamlParser.parse(content) : new JSON().fromJSON(content);
+            }
+            catch (RuntimeException x)
+            {
+                throw new IllegalArgumentException("Invalid authorization policy", x);
+            }
+            if (!(parsed instanceof Map))
+                throw new IllegalArgumentException("Invalid authorization policy");
+            Map<?, ?> members = (Map<?, ?>)parsed;
+            checkMembers(members, Set.of("default", "rules"), "authorization policy");
+
+            Object value = members.get("default");
+            Decision defaultDecision = value == null ? Decision.NOT_APPLICABLE : asDecision(value);
+            List<Rule> rules = new ArrayList<>();
+            for (Object rule : asArray(members.get("rules"), "rules"))
+            {
+                if (!(rule instanceof Map))
+                    throw new IllegalArgumentException("Invalid rule " + rule);
+                rules.add(Rule.from((Map<?, ?>)rule, rules.size()));
+            }
+            return new Policy(defaultDecision, Collections.unmodifiableList(rules));
+        }
+    }
+
+    private static class Rule
+    {
+        private static final Set<String> MEMBERS = Set.of("name", "effect", "path", "methods", "authenticated", "roles", "user", "attributes", "time");
+
+        private final String name;
+        private final Decision effect;
+        private final PathSpec pathSpec;
+        private final Set<String> methods;
+        private final Boolean authenticated;
+        private final Set<String> roles;
+        private final String user;
+        private final Map<String, String> attributes;
+        private final TimeWindow time;
+
+        private Rule(String name, Decision effect, PathSpec pathSpec, Set<String> methods, Boolean authenticated, Set<String> roles, String user, Map<String, String> attributes, TimeWindow time)
+        {
+            this.name = name;
+            this.effect = effect;
+            this.pathSpec = pathSpec;
+            this.methods = methods;
+            this.authenticated = authenticated;
+            this.roles = roles;
+            this.user = user;
+            this.attributes = attributes;
+            this.time = time;
+        }
+
+        private static Rule from(Map<?, ?> members, int index)
+        {
+            Object value = members.get("name");
+            String name = value == null ? "#" + index : value.toString();
+            String description = "rule " + name;
+            checkMembers(members, MEMBERS, description);
+
+            value = members.get("effect");
+            if (value == null)
+                throw new IllegalArgumentException("Missing effect in " + description);
+            Decision effect = asDecision(value);
+            if (effect == Decision.NOT_APPLICABLE)
+                throw new IllegalArgumentException("Invalid effect " + value + " in " + description);
+
+            PathSpec pathSpec = null;
+            value = members.get("path");
+            if (value != null)
+            {
+                String path = value.toString();
+                pathSpec = path.indexOf('{') >= 0 ? new UriTemplatePathSpec(path) : PathSpec.from(path);
+            }
+
+            Set<String> methods = null;
+            value = members.get("methods");
+            if (value != null)
+            {
+                methods = new HashSet<>();
+                for (Object method : asArray(value, "methods"))
+                {
+                    methods.add(method.toString().toUpperCase(Locale.ENGLISH));
+                }
+            }
+
+            value = members.get("authenticated");
+            if (value != null && !(value instanceof Boolean))
+                throw new IllegalArgumentException("Invalid authenticated " + value + " in " + description);
+            Boolean authenticated = (Boolean)value;
+
+            Set<String> roles = null;
+            value = members.get("roles");
+            if (value != null)
+            {
+                roles = new HashSet<>();
+                for (Object role : asArray(value, "roles"))
+                {
+                    roles.add(role.toString());
+                }
+            }
+
+            value = members.get("user");
+            String user = value == null ? null : value.toString();
+
+            Map<String, String> attributes = null;
+            value = members.get("attributes");
+            if (value != null)
+            {
+                if (!(value instanceof Map))
+                    throw new IllegalArgumentException("Invalid attributes in " + description);
+                attributes = new LinkedHashMap<>();
+                for (Map.Entry<?, ?> entry : ((Map<?, ?>)value).entrySet())
+                {
+                    attributes.put(entry.getKey().toString(), String.valueOf(entry.getValue()));
+                }
+            }
+
+            value = members.get("time");
+            TimeWindow time = null;
+            if (value != null)
+            {
+                if (!(value instanceof Map))
+                    throw new IllegalArgumentException("Invalid time in " + description);
+                time = TimeWindow.from((Map<?, ?>)value, description);
+            }
+
+            return new Rule(name, effect, pathSpec, methods, authenticated, roles, user, attributes, time);
+        }
+
+        private boolean matches(Request request, String pathInContext, UserIdentity userIdentity, Clock clock)
+        {
+            Map<String, String> variables = Map.of();
+            if (pathSpec != null)
+            {
+                if (!pathSpec.matches(pathInContext))
+                    return false;
+                if (pathSpec instanceof UriTemplatePathSpec)
+                    variables = ((UriTemplatePathSpec)pathSpec).getPathParams(pathInContext);
+            }
+
+            if (methods != null && !methods.contains(request.getMethod()))
+                return false;
+
+            if (authenticated != null && authenticated != (userIdentity != null))
+                return false;
+
+            if (roles != null)
+            {
+                if (userIdentity == null)
+                    return false;
+                if (roles.stream().noneMatch(role -> userIdentity.isUserInRole(role, null)))
+                    return false;
+            }
+
+            if (user != null)
+            {
+                if (userIdentity == null)
+                    return false;
+                if (!resolve(user, variables).equals(userIdentity.getUserPrincipal().getName()))
+                    return false;
+            }
+
+            if (attributes != null)
+            {
+                for (Map.Entry<String, String> entry : attributes.entrySet())
+                {
+                    Object attribute = request.getAttribute(entry.getKey());
+                    if (attribute == null || !resolve(entry.getValue(), variables).equals(attribute.toString()))
+                        return false;
+                }
+            }
+
+            return time == null || time.includes(clock);
+        }
+
+        private static String resolve(String expression, Map<String, String> variables)
+        {
+            if (variables.isEmpty() || expression.indexOf('{') < 0)
+                return expression;
+            StringBuilder builder = new StringBuilder();
+            int start = 0;
+            while (true)
+            {
+                int open = expression.indexOf('{', start);
+                int close = open < 0 ? -1 : expression.indexOf('}', open);
+                if (close < 0)
+                    break;
+                String value = variables.get(expression.substring(open + 1, close));
+                builder.append(expression, start, open).append(value == null ? expression.substring(open, close + 1) : value);
+                start = close + 1;
+            }
+            return builder.append(expression, start, expression.length()).toString();
+        }
+    }
+
+    private static class TimeWindow
+    {
+        private static final Set<String> MEMBERS = Set.of("days", "from", "to", "zone");
+
+        private final Set<DayOfWeek> days;
+        private final LocalTime from;
+        private final LocalTime to;
+        private final ZoneId zone;
+
+        private TimeWindow(Set<DayOfWeek> days, LocalTime from, LocalTime to, ZoneId zone)
+        {
+            this.days = days;
+            this.from = from;
+            this.to = to;
+            this.zone = zone;
+        }
+
+        private static TimeWindow from(Map<?, ?> members, String description)
+        {
+            checkMembers(members, MEMBERS, "time of " + description);
+            try
+            {
+                Set<DayOfWeek> days = null;
+                Object value = members.get("days");
+                if (value != null)
+                {
+                    days = EnumSet.noneOf(DayOfWeek.class);
+                    for (Object day : asArray(value, "days"))
+                    {
+                        days.add(DayOfWeek.valueOf(day.toString().toUpperCase(Locale.ENGLISH)));
+                    }
+                }
+                value = members.get("from");
+                LocalTime from = value == null ? null : LocalTime.parse(value.toString());
+                value = members.get("to");
+                LocalTime to = value == null ? null : LocalTime.parse(value.toString());
+                value = members.get("zone");
+                ZoneId zone = value == null ? null : ZoneId.of(value.toString());
+                return new TimeWindow(days, from, to, zone);
+            }
+            catch (RuntimeException x)
+            {
+                throw new IllegalArgumentException("Invalid time in " + description, x);
+            }
+        }
+
+        private boolean includes(Clock clock)
+        {
+            ZonedDateTime now = ZonedDateTime.now(zone == null ? clock : clock.withZone(zone));
+            if (days != null && !days.contains(now.getDayOfWeek()))
+                return false;
+            LocalTime time = now.toLocalTime();
+            // A window such as 22:00 to 06:00 spans midnight.
+            if (from != null && to != null && to.isBefore(from))
+                return !time.isBefore(from) || time.isBefore(to);
+            if (from != null && time.isBefore(from))
+                return false;
+            return to == null || time.isBefore(to);
+        }
+    }
+
+    private static void checkMembers(Map<?, ?> members, Set<String> allowed, String description)
+    {
+        for (Object key : members.keySet())
+        {
+            if (!allowed.contains(key))
+                throw new IllegalArgumentException("Unknown member " + key + " in " + description);
+        }
+    }
+
+    private static Object[] asArray(Object value, String name)
+    {
+        if (value == null)
+            return new Object[0];
+        if (value instanceof Object[])
+            return (Object[])value;
+        if (value instanceof List)
+            return ((List<?>)value).toArray();
+        if (value instanceof String)
+            return new Object[]{value};
+        throw new IllegalArgumentException("Invalid " + name + " " + value);
+    }
+
+    private static Decision asDecision(Object value)
+    {
+        try
+        {
+            return Decision.valueOf(value.toString().toUpperCase(Locale.ENGLISH));
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new IllegalArgumentException("Invalid decision " + value, x);
+        }
+    }
+}
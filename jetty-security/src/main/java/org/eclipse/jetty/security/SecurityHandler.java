@@ -30,6 +30,7 @@ import javax.servlet.http.HttpServletResponse;
 import org.eclipse.jetty.security.authentication.DeferredAuthentication;
 import org.eclipse.jetty.server.Authentication;
 import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Response;
 import org.eclipse.jetty.server.UserIdentity;
@@ -69,6 +70,7 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
     private LoginService _loginService;
     private IdentityService _identityService;
     private boolean _renewSession = true;
+    private AuthorizationPolicy _authorizationPolicy;
 
     static
     {
@@ -106,6 +108,28 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
         _identityService = identityService;
     }
 
+    /**
+     * @return the authorization policy consulted for each request, or null if there is none
+     */
+    public AuthorizationPolicy getAuthorizationPolicy()
+    {
+        return _authorizationPolicy;
+    }
+
+    /**
+     * <p>Sets the authorization policy consulted for each request, after the
+     * {@link ConstraintMapping}s have permitted it.</p>
+     *
+     * @param authorizationPolicy the authorization policy, or null for no policy
+     */
+    public void setAuthorizationPolicy(AuthorizationPolicy authorizationPolicy)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        updateBean(_authorizationPolicy, authorizationPolicy);
+        _authorizationPolicy = authorizationPolicy;
+    }
+
     /**
      * Get the loginService.
      *
@@ -535,7 +559,14 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
                         }
                     }
 
-                    handler.handle(pathInContext, baseRequest, request, response);
+                    if (!checkAuthorizationPolicy(pathInContext, baseRequest, userAuth.getUserIdentity()))
+                    {
+                        response.sendError(HttpServletResponse.SC_FORBIDDEN, "!policy");
+                        baseRequest.setHandled(true);
+                        return;
+                    }
+
+                    HandlerInstrumentation.handle(handler, pathInContext, baseRequest, request, response);
                     if (authenticator != null)
                         authenticator.secureResponse(request, response, isAuthMandatory, userAuth);
                 }
@@ -546,7 +577,25 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
 
                     try
                     {
-                        handler.handle(pathInContext, baseRequest, request, response);
+                        if (!checkAuthorizationPolicy(pathInContext, baseRequest, null))
+                        {
+                            // The policy may permit the request once it is authenticated.
+                            Authentication auth = deferred.authenticate(request, response);
+                            if (auth instanceof Authentication.ResponseSent)
+                            {
+                                baseRequest.setHandled(true);
+                                return;
+                            }
+                            if (!(auth instanceof Authentication.User) ||
+                                !checkAuthorizationPolicy(pathInContext, baseRequest, ((Authentication.User)auth).getUserIdentity()))
+                            {
+                                response.sendError(HttpServletResponse.SC_FORBIDDEN, "!policy");
+                                baseRequest.setHandled(true);
+                                return;
+                            }
+                            baseRequest.setAuthentication(auth);
+                        }
+                        HandlerInstrumentation.handle(handler, pathInContext, baseRequest, request, response);
                     }
                     finally
                     {
@@ -575,7 +624,13 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
                     baseRequest.setAuthentication(authentication);
                     if (_identityService != null)
                         previousIdentity = _identityService.associate(null);
-                    handler.handle(pathInContext, baseRequest, request, response);
+                    if (!checkAuthorizationPolicy(pathInContext, baseRequest, null))
+                    {
+                        response.sendError(HttpServletResponse.SC_FORBIDDEN, "!policy");
+                        baseRequest.setHandled(true);
+                        return;
+                    }
+                    HandlerInstrumentation.handle(handler, pathInContext, baseRequest, request, response);
                     if (authenticator != null)
                         authenticator.secureResponse(request, response, isAuthMandatory, null);
                 }
@@ -593,7 +648,7 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
             }
         }
         else
-            handler.handle(pathInContext, baseRequest, request, response);
+            HandlerInstrumentation.handle(handler, pathInContext, baseRequest, request, response);
     }
 
     public static SecurityHandler getCurrentSecurityHandler()
@@ -628,6 +683,20 @@ public abstract class SecurityHandler extends HandlerWrapper implements Authenti
 
     protected abstract RoleInfo prepareConstraintInfo(String pathInContext, Request request);
 
+    /**
+     * @param pathInContext the path of the request within the context
+     * @param request the request
+     * @param userIdentity the authenticated user identity, or null if the request is not authenticated
+     * @return whether the {@link #getAuthorizationPolicy() authorization policy}, if any, does not deny the request
+     */
+    protected boolean checkAuthorizationPolicy(String pathInContext, Request request, UserIdentity userIdentity)
+    {
+        AuthorizationPolicy policy = _authorizationPolicy;
+        if (policy == null)
+            return true;
+        return policy.authorize(request, pathInContext, userIdentity) != AuthorizationPolicy.Decision.DENY;
+    }
+
     protected abstract boolean checkUserDataPermissions(String pathInContext, Request request, Response response, RoleInfo constraintInfo) throws IOException;
 
     protected abstract boolean isAuthMandatory(Request baseRequest, Response baseResponse, Object constraintInfo);
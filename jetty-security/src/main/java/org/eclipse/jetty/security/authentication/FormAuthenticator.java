@@ -177,6 +177,12 @@ public class FormAuthenticator extends LoginAuthenticator
             HttpSession session = ((HttpServletRequest)request).getSession(true);
             Authentication cached = new SessionAuthentication(getAuthMethod(), user, password);
             session.setAttribute(SessionAuthentication.__J_AUTHENTICATED, cached);
+            // The session may have just been created, so register it now.
+            if (!registerSession((HttpServletRequest)request, user))
+            {
+                session.removeAttribute(SessionAuthentication.__J_AUTHENTICATED);
+                return null;
+            }
         }
         return user;
     }
@@ -0,0 +1,275 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.math.BigInteger;
+import java.nio.charset.StandardCharsets;
+import java.security.AlgorithmParameters;
+import java.security.GeneralSecurityException;
+import java.security.Key;
+import java.security.KeyFactory;
+import java.security.MessageDigest;
+import java.security.spec.ECGenParameterSpec;
+import java.security.spec.ECParameterSpec;
+import java.security.spec.ECPoint;
+import java.security.spec.ECPublicKeySpec;
+import java.security.spec.RSAPublicKeySpec;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import javax.crypto.SecretKey;
+import javax.crypto.spec.SecretKeySpec;
+
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>A JSON Web Key (JWK), as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc7517">RFC 7517</a>.</p>
+ * <p>The supported key types are RSA public keys, EC public keys on the {@code P-256}
+ * curve and {@code oct} symmetric keys for HMAC.</p>
+ */
+public class JsonWebKey
+{
+    private final String _keyId;
+    private final String _algorithm;
+    private final String _use;
+    private final Key _key;
+    private final String _thumbprint;
+
+    /**
+     * @param keyId the key id, or null
+     * @param key the key, either a {@code java.security.interfaces.RSAPublicKey},
+     * a {@code java.security.interfaces.ECPublicKey} or a {@link SecretKey}
+     */
+    public JsonWebKey(String keyId, Key key)
+    {
+        this(keyId, null, null, key, null);
+    }
+
+    private JsonWebKey(String keyId, String algorithm, String use, Key key, String thumbprint)
+    {
+        _keyId = keyId;
+        _algorithm = algorithm;
+        _use = use;
+        _key = Objects.requireNonNull(key);
+        _thumbprint = thumbprint;
+    }
+
+    /**
+     * <p>Creates a key for {@code HS256} signatures from the given secret.</p>
+     *
+     * @param keyId the key id, or null
+     * @param secret the shared secret
+     * @return the HMAC key
+     */
+    public static JsonWebKey hmac(String keyId, byte[] secret)
+    {
+        return new JsonWebKey(keyId, JsonWebToken.HS256, null, new SecretKeySpec(secret, "HmacSHA256"), null);
+    }
+
+    /**
+     * <p>Parses a JWK Set document, ignoring the keys whose type is not supported.</p>
+     *
+     * @param json the JWK Set document
+     * @return the keys of the set
+     * @throws IllegalArgumentException if the document is not a JWK Set
+     */
+    public static List<JsonWebKey> parseSet(String json)
+    {
+        Object parsed;
+        try
+        {
+            parsed = new JSON().fromJSON(json);
+        }
+        catch (RuntimeException x)
+        {
+            throw new IllegalArgumentException("Invalid JWK Set", x);
+        }
+        Object keys = parsed instanceof Map ? ((Map<?, ?>)parsed).get("keys") : null;
+        if (!(keys instanceof Object[]))
+            throw new IllegalArgumentException("Invalid JWK Set");
+        List<JsonWebKey> result = new ArrayList<>();
+        for (Object key : (Object[])keys)
+        {
+            if (!(key instanceof Map))
+                continue;
+            try
+            {
+                result.add(from((Map<?, ?>)key));
+            }
+            catch (IllegalArgumentException ignored)
+            {
+                // Unsupported or invalid key, skip it.
+            }
+        }
+        return result;
+    }
+
+    /**
+     * <p>Creates a key from its JSON members.</p>
+     *
+     * @param jwk the JSON members of the key
+     * @return the key
+     * @throws IllegalArgumentException if the key is invalid or its type is not supported
+     */
+    public static JsonWebKey from(Map<?, ?> jwk)
+    {
+        String kty = member(jwk, "kty");
+        String kid = JsonWebToken.asString(jwk.get("kid"));
+        String alg = JsonWebToken.asString(jwk.get("alg"));
+        String use = JsonWebToken.asString(jwk.get("use"));
+        try
+        {
+            switch (kty)
+            {
+                case "RSA":
+                {
+                    if (jwk.containsKey("d"))
+                        throw new IllegalArgumentException("Private JWK");
+                    String n = member(jwk, "n");
+                    String e = member(jwk, "e");
+                    RSAPublicKeySpec spec = new RSAPublicKeySpec(toBigInteger(n), toBigInteger(e));
+                    Key key = KeyFactory.getInstance("RSA").generatePublic(spec);
+                    String thumbprint = thumbprint("{\"e\":\"" + e + "\",\"kty\":\"RSA\",\"n\":\"" + n + "\"}");
+                    return new JsonWebKey(kid, alg, use, key, thumbprint);
+                }
+                case "EC":
+                {
+                    if (jwk.containsKey("d"))
+                        throw new IllegalArgumentException("Private JWK");
+                    String crv = member(jwk, "crv");
+                    if (!"P-256".equals(crv))
+                        throw new IllegalArgumentException("Unsupported JWK curve " + crv);
+                    String x = member(jwk, "x");
+                    String y = member(jwk, "y");
+                    AlgorithmParameters parameters = AlgorithmParameters.getInstance("EC");
+                    parameters.init(new ECGenParameterSpec("secp256r1"));
+                    ECPoint point = new ECPoint(toBigInteger(x), toBigInteger(y));
+                    ECPublicKeySpec spec = new ECPublicKeySpec(point, parameters.getParameterSpec(ECParameterSpec.class));
+                    Key key = KeyFactory.getInstance("EC").generatePublic(spec);
+                    String thumbprint = thumbprint("{\"crv\":\"" + crv + "\",\"kty\":\"EC\",\"x\":\"" + x + "\",\"y\":\"" + y + "\"}");
+                    return new JsonWebKey(kid, alg, use, key, thumbprint);
+                }
+                case "oct":
+                {
+                    String k = member(jwk, "k");
+                    Key key = new SecretKeySpec(Base64.getUrlDecoder().decode(k), "HmacSHA256");
+                    return new JsonWebKey(kid, alg, use, key, thumbprint("{\"k\":\"" + k + "\",\"kty\":\"oct\"}"));
+                }
+                default:
+                    throw new IllegalArgumentException("Unsupported JWK type " + kty);
+            }
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new IllegalArgumentException("Invalid JWK", x);
+        }
+    }
+
+    private static String member(Map<?, ?> jwk, String name)
+    {
+        String value = JsonWebToken.asString(jwk.get(name));
+        if (value == null)
+            throw new IllegalArgumentException("Missing JWK member " + name);
+        return value;
+    }
+
+    private static BigInteger toBigInteger(String base64)
+    {
+        return new BigInteger(1, Base64.getUrlDecoder().decode(base64));
+    }
+
+    private static String thumbprint(String canonical) throws GeneralSecurityException
+    {
+        byte[] digest = MessageDigest.getInstance("SHA-256").digest(canonical.getBytes(StandardCharsets.UTF_8));
+        return Base64.getUrlEncoder().withoutPadding().encodeToString(digest);
+    }
+
+    /**
+     * @return the {@code kid} of this key, or null
+     */
+    public String getKeyId()
+    {
+        return _keyId;
+    }
+
+    /**
+     * @return the {@code alg} of this key, or null if this key may be used with any compatible algorithm
+     */
+    public String getAlgorithm()
+    {
+        return _algorithm;
+    }
+
+    /**
+     * @return the {@code use} of this key, or null
+     */
+    public String getUse()
+    {
+        return _use;
+    }
+
+    /**
+     * @return the key
+     */
+    public Key getKey()
+    {
+        return _key;
+    }
+
+    /**
+     * @return the JWK thumbprint of this key, as defined by
+     * <a href="https://datatracker.ietf.org/doc/html/rfc7638">RFC 7638</a>,
+     * or null if this key was not created from its JSON members
+     */
+    public String getThumbprint()
+    {
+        return _thumbprint;
+    }
+
+    /**
+     * <p>Returns whether this key can verify the signatures made with the given algorithm.</p>
+     *
+     * @param algorithm the signature algorithm
+     * @return whether this key can verify the signatures made with the given algorithm
+     */
+    public boolean isCompatible(String algorithm)
+    {
+        if (algorithm == null)
+            return false;
+        if (_algorithm != null && !_algorithm.equals(algorithm))
+            return false;
+        if (_use != null && !"sig".equals(_use))
+            return false;
+        switch (algorithm)
+        {
+            case JsonWebToken.RS256:
+                return "RSA".equals(_key.getAlgorithm());
+            case JsonWebToken.ES256:
+                return "EC".equals(_key.getAlgorithm());
+            case JsonWebToken.HS256:
+                return _key instanceof SecretKey;
+            default:
+                return false;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[kid=%s,alg=%s,type=%s]", getClass().getSimpleName(), hashCode(), _keyId, _algorithm, _key.getAlgorithm());
+    }
+}
@@ -0,0 +1,274 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.Key;
+import java.security.MessageDigest;
+import java.security.PublicKey;
+import java.security.Signature;
+import java.security.interfaces.ECPublicKey;
+import java.security.interfaces.RSAPublicKey;
+import java.time.Instant;
+import java.util.Base64;
+import java.util.Collection;
+import java.util.Collections;
+import java.util.List;
+import java.util.Map;
+import java.util.stream.Collectors;
+import java.util.stream.Stream;
+import javax.crypto.Mac;
+import javax.crypto.SecretKey;
+
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>A JSON Web Token (JWT) in JWS compact serialization, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc7519">RFC 7519</a>.</p>
+ * <p>Parsing a token does not verify it: the signature must be verified
+ * with {@link #verify(Key)} before the claims can be trusted.</p>
+ * <p>The supported signature algorithms are {@code RS256}, {@code ES256}
+ * and {@code HS256}.</p>
+ */
+public class JsonWebToken
+{
+    public static final String RS256 = "RS256";
+    public static final String ES256 = "ES256";
+    public static final String HS256 = "HS256";
+
+    private final String _token;
+    private final Map<String, Object> _header;
+    private final Map<String, Object> _claims;
+    private final String _signingInput;
+    private final byte[] _signature;
+
+    private JsonWebToken(String token, Map<String, Object> header, Map<String, Object> claims, String signingInput, byte[] signature)
+    {
+        _token = token;
+        _header = header;
+        _claims = claims;
+        _signingInput = signingInput;
+        _signature = signature;
+    }
+
+    /**
+     * <p>Parses the given token, without verifying it.</p>
+     *
+     * @param token the token in JWS compact serialization
+     * @return the parsed token
+     * @throws IllegalArgumentException if the token is malformed
+     */
+    public static JsonWebToken parse(String token)
+    {
+        if (token == null)
+            throw new IllegalArgumentException("Missing token");
+        int dot1 = token.indexOf('.');
+        int dot2 = dot1 < 0 ? -1 : token.indexOf('.', dot1 + 1);
+        if (dot2 < 0 || token.indexOf('.', dot2 + 1) >= 0)
+            throw new IllegalArgumentException("JWT does not contain 3 sections");
+
+        Base64.Decoder decoder = Base64.getUrlDecoder();
+        Map<String, Object> header = toMap(decoder.decode(token.substring(0, dot1)), "header");
+        Map<String, Object> claims = toMap(decoder.decode(token.substring(dot1 + 1, dot2)), "claims");
+        byte[] signature = decoder.decode(token.substring(dot2 + 1));
+        return new JsonWebToken(token, header, claims, token.substring(0, dot2), signature);
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> toMap(byte[] bytes, String section)
+    {
+        Object json;
+        try
+        {
+            json = new JSON().fromJSON(new String(bytes, StandardCharsets.UTF_8));
+        }
+        catch (RuntimeException x)
+        {
+            throw new IllegalArgumentException("Invalid JWT " + section, x);
+        }
+        if (!(json instanceof Map))
+            throw new IllegalArgumentException("Invalid JWT " + section);
+        return Collections.unmodifiableMap((Map<String, Object>)json);
+    }
+
+    /**
+     * @return the token in JWS compact serialization
+     */
+    public String getToken()
+    {
+        return _token;
+    }
+
+    /**
+     * @return the JOSE header of the token
+     */
+    public Map<String, Object> getHeader()
+    {
+        return _header;
+    }
+
+    /**
+     * @return the claims of the token
+     */
+    public Map<String, Object> getClaims()
+    {
+        return _claims;
+    }
+
+    /**
+     * @param name the claim name
+     * @return the claim value, or null if the token does not have the claim
+     */
+    public Object getClaim(String name)
+    {
+        return _claims.get(name);
+    }
+
+    /**
+     * @return the {@code alg} header value
+     */
+    public String getAlgorithm()
+    {
+        return asString(_header.get("alg"));
+    }
+
+    /**
+     * @return the {@code kid} header value, or null
+     */
+    public String getKeyId()
+    {
+        return asString(_header.get("kid"));
+    }
+
+    /**
+     * @return the {@code typ} header value, or null
+     */
+    public String getType()
+    {
+        return asString(_header.get("typ"));
+    }
+
+    /**
+     * @return the {@code iss} claim value, or null
+     */
+    public String getIssuer()
+    {
+        return asString(_claims.get("iss"));
+    }
+
+    /**
+     * @return the {@code sub} claim value, or null
+     */
+    public String getSubject()
+    {
+        return asString(_claims.get("sub"));
+    }
+
+    /**
+     * @return the {@code aud} claim values, possibly empty
+     */
+    public List<String> getAudience()
+    {
+        return asStrings(_claims.get("aud"));
+    }
+
+    /**
+     * @return the {@code exp} claim value, or null
+     */
+    public Instant getExpiresAt()
+    {
+        return asInstant(_claims.get("exp"));
+    }
+
+    /**
+     * @return the {@code nbf} claim value, or null
+     */
+    public Instant getNotBefore()
+    {
+        return asInstant(_claims.get("nbf"));
+    }
+
+    /**
+     * @return the {@code iat} claim value, or null
+     */
+    public Instant getIssuedAt()
+    {
+        return asInstant(_claims.get("iat"));
+    }
+
+    /**
+     * <p>Verifies the signature of this token with the given key.</p>
+     * <p>The key type must match the token algorithm, so that for example
+     * an RSA public key cannot be used as an HMAC secret.</p>
+     *
+     * @param key the key to verify the signature with
+     * @return whether the signature is valid
+     * @throws GeneralSecurityException if the signature cannot be verified
+     */
+    public boolean verify(Key key) throws GeneralSecurityException
+    {
+        String algorithm = getAlgorithm();
+        if (RS256.equals(algorithm) && key instanceof RSAPublicKey)
+            return verify("SHA256withRSA", (PublicKey)key);
+        if (ES256.equals(algorithm) && key instanceof ECPublicKey)
+            return _signature.length == 64 && verify("SHA256withECDSAinP1363Format", (PublicKey)key);
+        if (HS256.equals(algorithm) && key instanceof SecretKey)
+        {
+            Mac mac = Mac.getInstance("HmacSHA256");
+            mac.init(key);
+            byte[] expected = mac.doFinal(_signingInput.getBytes(StandardCharsets.US_ASCII));
+            return MessageDigest.isEqual(expected, _signature);
+        }
+        return false;
+    }
+
+    private boolean verify(String algorithm, PublicKey key) throws GeneralSecurityException
+    {
+        Signature signature = Signature.getInstance(algorithm);
+        signature.initVerify(key);
+        signature.update(_signingInput.getBytes(StandardCharsets.US_ASCII));
+        return signature.verify(_signature);
+    }
+
+    static String asString(Object value)
+    {
+        return value instanceof String ? (String)value : null;
+    }
+
+    static List<String> asStrings(Object value)
+    {
+        if (value instanceof String)
+            return List.of((String)value);
+        Stream<?> stream;
+        if (value instanceof Collection)
+            stream = ((Collection<?>)value).stream();
+        else if (value instanceof Object[])
+            stream = Stream.of((Object[])value);
+        else
+            return List.of();
+        return stream.filter(String.class::isInstance).map(String.class::cast).collect(Collectors.toList());
+    }
+
+    private static Instant asInstant(Object value)
+    {
+        return value instanceof Number ? Instant.ofEpochSecond(((Number)value).longValue()) : null;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[alg=%s,kid=%s,iss=%s,sub=%s]", getClass().getSimpleName(), hashCode(), getAlgorithm(), getKeyId(), getIssuer(), getSubject());
+    }
+}
@@ -0,0 +1,219 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.net.HttpURLConnection;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link JwtKeyProvider} that fetches the keys from a JWK Set endpoint,
+ * typically the {@code jwks_uri} of an OAuth 2.0 authorization server.</p>
+ * <p>The keys are cached and fetched again after the {@link #getRefreshInterval() refresh interval}.
+ * When a token refers to a key id that is not cached, for example because the
+ * authorization server rotated its keys, the keys are fetched again, but not more
+ * often than the {@link #getMinRefreshInterval() minimum refresh interval}, so that
+ * tokens with bogus key ids cannot be used to flood the endpoint.</p>
+ * <p>If the keys cannot be fetched again, the previously fetched keys are used.</p>
+ */
+public class JwksKeyProvider implements JwtKeyProvider
+{
+    private static final Logger LOG = LoggerFactory.getLogger(JwksKeyProvider.class);
+
+    private final URI _uri;
+    private long _refreshInterval = TimeUnit.HOURS.toMillis(1);
+    private long _minRefreshInterval = TimeUnit.SECONDS.toMillis(30);
+    private int _connectTimeout = 5000;
+    private int _readTimeout = 5000;
+    private volatile Keys _keys;
+
+    /**
+     * @param uri the URI of the JWK Set endpoint
+     */
+    public JwksKeyProvider(URI uri)
+    {
+        _uri = Objects.requireNonNull(uri);
+    }
+
+    /**
+     * @return the URI of the JWK Set endpoint
+     */
+    public URI getURI()
+    {
+        return _uri;
+    }
+
+    /**
+     * @return the interval in milliseconds after which the cached keys are fetched again
+     */
+    public long getRefreshInterval()
+    {
+        return _refreshInterval;
+    }
+
+    /**
+     * @param refreshInterval the interval in milliseconds after which the cached keys are fetched again
+     */
+    public void setRefreshInterval(long refreshInterval)
+    {
+        _refreshInterval = refreshInterval;
+    }
+
+    /**
+     * @return the minimum interval in milliseconds between two fetches of the keys
+     */
+    public long getMinRefreshInterval()
+    {
+        return _minRefreshInterval;
+    }
+
+    /**
+     * @param minRefreshInterval the minimum interval in milliseconds between two fetches of the keys
+     */
+    public void setMinRefreshInterval(long minRefreshInterval)
+    {
+        _minRefreshInterval = minRefreshInterval;
+    }
+
+    /**
+     * @return the connect timeout in milliseconds to the JWK Set endpoint
+     */
+    public int getConnectTimeout()
+    {
+        return _connectTimeout;
+    }
+
+    /**
+     * @param connectTimeout the connect timeout in milliseconds to the JWK Set endpoint
+     */
+    public void setConnectTimeout(int connectTimeout)
+    {
+        _connectTimeout = connectTimeout;
+    }
+
+    /**
+     * @return the read timeout in milliseconds from the JWK Set endpoint
+     */
+    public int getReadTimeout()
+    {
+        return _readTimeout;
+    }
+
+    /**
+     * @param readTimeout the read timeout in milliseconds from the JWK Set endpoint
+     */
+    public void setReadTimeout(int readTimeout)
+    {
+        _readTimeout = readTimeout;
+    }
+
+    @Override
+    public JsonWebKey getKey(JsonWebToken token) throws IOException
+    {
+        Keys keys = _keys;
+        if (keys == null || NanoTime.millisSince(keys.fetched) >= _refreshInterval)
+            keys = refresh(keys, _refreshInterval);
+
+        JsonWebKey key = JwtKeyProvider.select(keys.list, token);
+        if (key == null && token.getKeyId() != null)
+        {
+            // The keys may have been rotated.
+            keys = refresh(keys, _minRefreshInterval);
+            key = JwtKeyProvider.select(keys.list, token);
+        }
+        return key;
+    }
+
+    private synchronized Keys refresh(Keys stale, long interval) throws IOException
+    {
+        // Another thread may have already refreshed the keys.
+        Keys keys = _keys;
+        if (keys != null && (keys != stale || NanoTime.millisSince(keys.fetched) < interval))
+            return keys;
+
+        try
+        {
+            List<JsonWebKey> list = JsonWebKey.parseSet(fetch(_uri));
+            if (LOG.isDebugEnabled())
+                LOG.debug("Fetched {} from {}", list, _uri);
+            keys = new Keys(list);
+        }
+        catch (IOException | IllegalArgumentException x)
+        {
+            if (keys == null)
+                throw x instanceof IOException ? (IOException)x : new IOException(x);
+            LOG.warn("Could not fetch JWK Set from {}, using cached keys", _uri, x);
+            // Do not retry immediately.
+            keys = new Keys(keys.list);
+        }
+        _keys = keys;
+        return keys;
+    }
+
+    /**
+     * <p>Fetches the JWK Set document from the given URI.</p>
+     *
+     * @param uri the URI of the JWK Set endpoint
+     * @return the JWK Set document
+     * @throws IOException if the document cannot be fetched
+     */
+    protected String fetch(URI uri) throws IOException
+    {
+        HttpURLConnection connection = (HttpURLConnection)uri.toURL().openConnection();
+        try
+        {
+            connection.setConnectTimeout(getConnectTimeout());
+            connection.setReadTimeout(getReadTimeout());
+            connection.setRequestProperty("Accept", "application/json");
+            int status = connection.getResponseCode();
+            if (status != HttpURLConnection.HTTP_OK)
+                throw new IOException("Unexpected response " + status + " from " + uri);
+            try (InputStream input = connection.getInputStream())
+            {
+                return IO.toString(input, StandardCharsets.UTF_8);
+            }
+        }
+        finally
+        {
+            connection.disconnect();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), _uri);
+    }
+
+    private static class Keys
+    {
+        private final List<JsonWebKey> list;
+        private final long fetched = NanoTime.now();
+
+        private Keys(List<JsonWebKey> list)
+        {
+            this.list = list;
+        }
+    }
+}
@@ -0,0 +1,598 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.io.IOException;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.InvalidKeyException;
+import java.security.MessageDigest;
+import java.security.SignatureException;
+import java.time.Instant;
+import java.util.Base64;
+import java.util.Collections;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.stream.Collectors;
+import javax.security.auth.Subject;
+import javax.servlet.ServletRequest;
+import javax.servlet.ServletResponse;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.security.EmptyLoginService;
+import org.eclipse.jetty.security.IdentityService;
+import org.eclipse.jetty.security.ServerAuthException;
+import org.eclipse.jetty.security.UserAuthentication;
+import org.eclipse.jetty.server.Authentication;
+import org.eclipse.jetty.server.Authentication.User;
+import org.eclipse.jetty.server.UserIdentity;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.security.Constraint;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link org.eclipse.jetty.security.Authenticator} for APIs protected by
+ * OAuth 2.0 bearer tokens in JSON Web Token format
+ * (<a href="https://datatracker.ietf.org/doc/html/rfc6750">RFC 6750</a>).</p>
+ * <p>The token signature is verified with the keys of a {@link JwtKeyProvider},
+ * typically a {@link JwksKeyProvider}, then the token expiration, the issuer and
+ * the audience are validated, allowing for a {@link #getClockSkew() clock skew}.
+ * The authenticated user is named after the {@link #getPrincipalClaim() principal claim}
+ * and has the roles returned by the {@link JwtRoleMapper}.</p>
+ * <p>Tokens bound to a key with DPoP
+ * (<a href="https://datatracker.ietf.org/doc/html/rfc9449">RFC 9449</a>)
+ * are accepted when {@link #getDPoP() DPoP} is enabled, and are then required
+ * to be presented with a valid DPoP proof of possession of the key.</p>
+ * <p>This authenticator does not require a {@link org.eclipse.jetty.security.LoginService},
+ * and may be configured with the {@code org.eclipse.jetty.security.jwt.*} init parameters
+ * when selected with the {@link Constraint#__JWT_AUTH} auth method.</p>
+ */
+public class JwtAuthenticator extends LoginAuthenticator
+{
+    private static final Logger LOG = LoggerFactory.getLogger(JwtAuthenticator.class);
+
+    public static final String __JWT_ISSUER = "org.eclipse.jetty.security.jwt.issuer";
+    public static final String __JWT_AUDIENCES = "org.eclipse.jetty.security.jwt.audiences";
+    public static final String __JWT_JWKS_URI = "org.eclipse.jetty.security.jwt.jwks_uri";
+    public static final String __JWT_ROLES_CLAIM = "org.eclipse.jetty.security.jwt.roles_claim";
+    public static final String __JWT_DPOP = "org.eclipse.jetty.security.jwt.dpop";
+
+    private static final String DPOP_HEADER = "DPoP";
+
+    /**
+     * <p>The support for DPoP-bound tokens.</p>
+     */
+    public enum DPoP
+    {
+        /**
+         * DPoP-bound tokens are rejected.
+         */
+        DISABLED,
+        /**
+         * Both bearer tokens and DPoP-bound tokens are accepted.
+         */
+        ENABLED,
+        /**
+         * Only DPoP-bound tokens are accepted.
+         */
+        REQUIRED
+    }
+
+    private final Map<String, Long> _proofs = new ConcurrentHashMap<>();
+    private final AtomicLong _proofsSweep = new AtomicLong(NanoTime.now());
+    private JwtKeyProvider _keyProvider;
+    private String _issuer;
+    private Set<String> _audiences = Set.of();
+    private Set<String> _algorithms = Collections.unmodifiableSet(new LinkedHashSet<>(List.of(JsonWebToken.RS256, JsonWebToken.ES256, JsonWebToken.HS256)));
+    private long _clockSkew = TimeUnit.SECONDS.toMillis(60);
+    private String _principalClaim = "sub";
+    private JwtRoleMapper _roleMapper = JwtRoleMapper.DEFAULT;
+    private DPoP _dpop = DPoP.DISABLED;
+    private long _dpopProofMaxAge = TimeUnit.SECONDS.toMillis(60);
+    private String _realmName;
+
+    public JwtAuthenticator()
+    {
+    }
+
+    /**
+     * @param keyProvider the provider of the keys that verify the token signatures
+     */
+    public JwtAuthenticator(JwtKeyProvider keyProvider)
+    {
+        _keyProvider = keyProvider;
+    }
+
+    /**
+     * @return the provider of the keys that verify the token signatures
+     */
+    public JwtKeyProvider getKeyProvider()
+    {
+        return _keyProvider;
+    }
+
+    /**
+     * @param keyProvider the provider of the keys that verify the token signatures
+     */
+    public void setKeyProvider(JwtKeyProvider keyProvider)
+    {
+        _keyProvider = keyProvider;
+    }
+
+    /**
+     * @return the required {@code iss} claim value, or null to accept any issuer
+     */
+    public String getIssuer()
+    {
+        return _issuer;
+    }
+
+    /**
+     * @param issuer the required {@code iss} claim value, or null to accept any issuer
+     */
+    public void setIssuer(String issuer)
+    {
+        _issuer = issuer;
+    }
+
+    /**
+     * @return the audiences of which at least one must be in the {@code aud} claim,
+     * or an empty set to accept any audience
+     */
+    public Set<String> getAudiences()
+    {
+        return _audiences;
+    }
+
+    /**
+     * @param audiences the audiences of which at least one must be in the {@code aud} claim,
+     * or no audiences to accept any audience
+     */
+    public void setAudiences(String... audiences)
+    {
+        _audiences = Collections.unmodifiableSet(new LinkedHashSet<>(List.of(audiences)));
+    }
+
+    /**
+     * @return the accepted signature algorithms
+     */
+    public Set<String> getAlgorithms()
+    {
+        return _algorithms;
+    }
+
+    /**
+     * @param algorithms the accepted signature algorithms, among {@code RS256}, {@code ES256} and {@code HS256}
+     */
+    public void setAlgorithms(String... algorithms)
+    {
+        _algorithms = Collections.unmodifiableSet(new LinkedHashSet<>(List.of(algorithms)));
+    }
+
+    /**
+     * @return the clock skew in milliseconds allowed when validating the token times
+     */
+    public long getClockSkew()
+    {
+        return _clockSkew;
+    }
+
+    /**
+     * @param clockSkew the clock skew in milliseconds allowed when validating the token times
+     */
+    public void setClockSkew(long clockSkew)
+    {
+        _clockSkew = clockSkew;
+    }
+
+    /**
+     * @return the claim that holds the name of the authenticated user
+     */
+    public String getPrincipalClaim()
+    {
+        return _principalClaim;
+    }
+
+    /**
+     * @param principalClaim the claim that holds the name of the authenticated user
+     */
+    public void setPrincipalClaim(String principalClaim)
+    {
+        _principalClaim = Objects.requireNonNull(principalClaim);
+    }
+
+    /**
+     * @return the mapper of the token claims to the user roles
+     */
+    public JwtRoleMapper getRoleMapper()
+    {
+        return _roleMapper;
+    }
+
+    /**
+     * @param roleMapper the mapper of the token claims to the user roles
+     */
+    public void setRoleMapper(JwtRoleMapper roleMapper)
+    {
+        _roleMapper = Objects.requireNonNull(roleMapper);
+    }
+
+    /**
+     * @return the support for DPoP-bound tokens
+     */
+    public DPoP getDPoP()
+    {
+        return _dpop;
+    }
+
+    /**
+     * @param dpop the support for DPoP-bound tokens
+     */
+    public void setDPoP(DPoP dpop)
+    {
+        _dpop = Objects.requireNonNull(dpop);
+    }
+
+    /**
+     * @return the maximum age in milliseconds of DPoP proofs
+     */
+    public long getDPoPProofMaxAge()
+    {
+        return _dpopProofMaxAge;
+    }
+
+    /**
+     * @param dpopProofMaxAge the maximum age in milliseconds of DPoP proofs
+     */
+    public void setDPoPProofMaxAge(long dpopProofMaxAge)
+    {
+        _dpopProofMaxAge = dpopProofMaxAge;
+    }
+
+    @Override
+    public String getAuthMethod()
+    {
+        return Constraint.__JWT_AUTH;
+    }
+
+    @Override
+    public void setConfiguration(AuthConfiguration configuration)
+    {
+        _identityService = configuration.getIdentityService();
+        if (_identityService == null)
+            throw new IllegalStateException("No IdentityService for " + this + " in " + configuration);
+        _loginService = configuration.getLoginService();
+        if (_loginService == null)
+        {
+            // Tokens are self-contained, a LoginService is only needed by DeferredAuthentication.
+            IdentityService identityService = _identityService;
+            _loginService = new EmptyLoginService()
+            {
+                @Override
+                public IdentityService getIdentityService()
+                {
+                    return identityService;
+                }
+            };
+        }
+        _realmName = configuration.getRealmName();
+
+        String issuer = configuration.getInitParameter(__JWT_ISSUER);
+        if (issuer != null)
+            setIssuer(issuer);
+        String audiences = configuration.getInitParameter(__JWT_AUDIENCES);
+        if (audiences != null)
+            setAudiences(StringUtil.csvSplit(audiences));
+        String jwksURI = configuration.getInitParameter(__JWT_JWKS_URI);
+        if (jwksURI != null)
+            setKeyProvider(new JwksKeyProvider(URI.create(jwksURI)));
+        String rolesClaim = configuration.getInitParameter(__JWT_ROLES_CLAIM);
+        if (rolesClaim != null)
+            setRoleMapper(JwtRoleMapper.claim(rolesClaim));
+        String dpop = configuration.getInitParameter(__JWT_DPOP);
+        if (dpop != null)
+            setDPoP(DPoP.valueOf(StringUtil.asciiToUpperCase(dpop.trim())));
+
+        if (_keyProvider == null)
+            throw new IllegalStateException("No JwtKeyProvider for " + this);
+    }
+
+    @Override
+    public Authentication validateRequest(ServletRequest req, ServletResponse res, boolean mandatory) throws ServerAuthException
+    {
+        if (!mandatory)
+            return new DeferredAuthentication(this);
+
+        HttpServletRequest request = (HttpServletRequest)req;
+        HttpServletResponse response = (HttpServletResponse)res;
+        String authorization = request.getHeader(HttpHeader.AUTHORIZATION.asString());
+
+        try
+        {
+            InvalidTokenException failure = null;
+            if (authorization != null)
+            {
+                int space = authorization.indexOf(' ');
+                if (space > 0)
+                {
+                    String scheme = authorization.substring(0, space);
+                    boolean bearer = "bearer".equalsIgnoreCase(scheme);
+                    boolean dpop = _dpop != DPoP.DISABLED && DPOP_HEADER.equalsIgnoreCase(scheme);
+                    if (bearer || dpop)
+                    {
+                        try
+                        {
+                            UserIdentity user = authenticate(authorization.substring(space + 1).trim(), dpop, request);
+                            return new UserAuthentication(getAuthMethod(), user);
+                        }
+                        catch (InvalidTokenException x)
+                        {
+                            if (LOG.isDebugEnabled())
+                                LOG.debug("Invalid token for {}", request.getRequestURI(), x);
+                            failure = x;
+                        }
+                    }
+                }
+            }
+
+            if (DeferredAuthentication.isDeferred(response))
+                return Authentication.UNAUTHENTICATED;
+
+            String realm = "realm=\"" + (_realmName == null ? "" : _realmName) + "\"";
+            String error = failure == null ? "" : ", error=\"" + failure.error + "\", error_description=\"" + failure.getMessage() + "\"";
+            if (_dpop != DPoP.REQUIRED)
+                response.addHeader(HttpHeader.WWW_AUTHENTICATE.asString(), "Bearer " + realm + (failure == null || failure.dpop ? "" : error));
+            if (_dpop != DPoP.DISABLED)
+            {
+                String algs = ", algs=\"" + String.join(" ", asymmetricAlgorithms()) + "\"";
+                response.addHeader(HttpHeader.WWW_AUTHENTICATE.asString(), "DPoP " + realm + (failure == null || !failure.dpop && _dpop != DPoP.REQUIRED ? "" : error) + algs);
+            }
+            response.sendError(HttpServletResponse.SC_UNAUTHORIZED);
+            return failure == null ? Authentication.SEND_CONTINUE : Authentication.SEND_FAILURE;
+        }
+        catch (IOException e)
+        {
+            throw new ServerAuthException(e);
+        }
+    }
+
+    private UserIdentity authenticate(String credentials, boolean dpop, HttpServletRequest request) throws InvalidTokenException, ServerAuthException
+    {
+        JsonWebToken token = parse(credentials, false);
+        // Proofs must not be usable as access tokens.
+        if ("dpop+jwt".equals(token.getType()))
+            throw new InvalidTokenException("invalid token type", false);
+        JsonWebKey key;
+        try
+        {
+            key = _keyProvider.getKey(token);
+        }
+        catch (IOException x)
+        {
+            throw new ServerAuthException(x);
+        }
+        if (key == null)
+            throw new InvalidTokenException("unknown key", false);
+        verify(token, key, false);
+
+        long now = System.currentTimeMillis();
+        Instant expiresAt = token.getExpiresAt();
+        if (expiresAt == null)
+            throw new InvalidTokenException("missing exp claim", false);
+        if (expiresAt.toEpochMilli() + _clockSkew <= now)
+            throw new InvalidTokenException("token expired", false);
+        Instant notBefore = token.getNotBefore();
+        if (notBefore != null && notBefore.toEpochMilli() - _clockSkew > now)
+            throw new InvalidTokenException("token not yet valid", false);
+        Instant issuedAt = token.getIssuedAt();
+        if (issuedAt != null && issuedAt.toEpochMilli() - _clockSkew > now)
+            throw new InvalidTokenException("token issued in the future", false);
+
+        if (_issuer != null && !_issuer.equals(token.getIssuer()))
+            throw new InvalidTokenException("invalid issuer", false);
+        if (!_audiences.isEmpty() && token.getAudience().stream().noneMatch(_audiences::contains))
+            throw new InvalidTokenException("invalid audience", false);
+
+        Object confirmation = token.getClaim("cnf");
+        String thumbprint = confirmation instanceof Map ? JsonWebToken.asString(((Map<?, ?>)confirmation).get("jkt")) : null;
+        if (dpop)
+        {
+            if (thumbprint == null)
+                throw new InvalidTokenException("token is not DPoP-bound", true);
+            validateProof(request, credentials, thumbprint, now);
+        }
+        else if (thumbprint != null || _dpop == DPoP.REQUIRED)
+        {
+            throw new InvalidTokenException("DPoP-bound token required", _dpop == DPoP.REQUIRED);
+        }
+
+        Object name = token.getClaim(_principalClaim);
+        if (!(name instanceof String))
+            throw new InvalidTokenException("missing " + _principalClaim + " claim", false);
+
+        JwtUserPrincipal principal = new JwtUserPrincipal((String)name, token);
+        Subject subject = new Subject();
+        subject.getPrincipals().add(principal);
+        subject.setReadOnly();
+        String[] roles = _roleMapper.getRoles(token);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Authenticated {} with roles {}", token, roles);
+        return _identityService.newUserIdentity(subject, principal, roles);
+    }
+
+    private void validateProof(HttpServletRequest request, String accessToken, String thumbprint, long now) throws InvalidTokenException, ServerAuthException
+    {
+        List<String> proofs = Collections.list(request.getHeaders(DPOP_HEADER));
+        if (proofs.size() != 1)
+            throw new InvalidTokenException("exactly one DPoP proof required", true);
+
+        JsonWebToken proof = parse(proofs.get(0), true);
+        if (!"dpop+jwt".equals(proof.getType()) || JsonWebToken.HS256.equals(proof.getAlgorithm()))
+            throw new InvalidTokenException("invalid proof type", true);
+        Object jwk = proof.getHeader().get("jwk");
+        JsonWebKey key;
+        try
+        {
+            key = jwk instanceof Map ? JsonWebKey.from((Map<?, ?>)jwk) : null;
+        }
+        catch (IllegalArgumentException x)
+        {
+            key = null;
+        }
+        if (key == null)
+            throw new InvalidTokenException("invalid proof key", true);
+        verify(proof, key, true);
+
+        if (!thumbprint.equals(key.getThumbprint()))
+            throw new InvalidTokenException("proof key does not match token binding", true);
+        if (!request.getMethod().equals(proof.getClaim("htm")))
+            throw new InvalidTokenException("proof method mismatch", true);
+        if (!matchesRequestURI(JsonWebToken.asString(proof.getClaim("htu")), request))
+            throw new InvalidTokenException("proof URI mismatch", true);
+        if (!sha256(accessToken).equals(proof.getClaim("ath")))
+            throw new InvalidTokenException("proof token hash mismatch", true);
+
+        Instant issuedAt = proof.getIssuedAt();
+        if (issuedAt == null || Math.abs(now - issuedAt.toEpochMilli()) > _dpopProofMaxAge + _clockSkew)
+            throw new InvalidTokenException("proof expired", true);
+
+        String jti = JsonWebToken.asString(proof.getClaim("jti"));
+        if (jti == null)
+            throw new InvalidTokenException("missing proof jti claim", true);
+        long nanoNow = NanoTime.now();
+        sweepProofs(nanoNow);
+        long expiry = nanoNow + TimeUnit.MILLISECONDS.toNanos(2 * (_dpopProofMaxAge + _clockSkew));
+        if (_proofs.putIfAbsent(jti, expiry) != null)
+            throw new InvalidTokenException("proof replayed", true);
+    }
+
+    private void sweepProofs(long now)
+    {
+        long sweep = _proofsSweep.get();
+        if (NanoTime.millisElapsed(sweep, now) < _dpopProofMaxAge || !_proofsSweep.compareAndSet(sweep, now))
+            return;
+        _proofs.values().removeIf(expiry -> NanoTime.isBefore(expiry, now));
+    }
+
+    private static boolean matchesRequestURI(String htu, HttpServletRequest request)
+    {
+        if (htu == null)
+            return false;
+        try
+        {
+            HttpURI uri = HttpURI.from(htu);
+            String scheme = request.getScheme();
+            return scheme.equalsIgnoreCase(uri.getScheme()) &&
+                request.getServerName().equalsIgnoreCase(uri.getHost()) &&
+                request.getServerPort() == (uri.getPort() > 0 ? uri.getPort() : HttpScheme.getDefaultPort(scheme)) &&
+                request.getRequestURI().equals(uri.getPath());
+        }
+        catch (IllegalArgumentException x)
+        {
+            return false;
+        }
+    }
+
+    private static String sha256(String value) throws ServerAuthException
+    {
+        try
+        {
+            byte[] digest = MessageDigest.getInstance("SHA-256").digest(value.getBytes(StandardCharsets.US_ASCII));
+            return Base64.getUrlEncoder().withoutPadding().encodeToString(digest);
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new ServerAuthException(x);
+        }
+    }
+
+    private JsonWebToken parse(String credentials, boolean proof) throws InvalidTokenException
+    {
+        JsonWebToken token;
+        try
+        {
+            token = JsonWebToken.parse(credentials);
+        }
+        catch (IllegalArgumentException x)
+        {
+            throw new InvalidTokenException("malformed token", proof);
+        }
+        String algorithm = token.getAlgorithm();
+        if (algorithm == null || !_algorithms.contains(algorithm))
+            throw new InvalidTokenException("unsupported algorithm", proof);
+        return token;
+    }
+
+    private void verify(JsonWebToken token, JsonWebKey key, boolean proof) throws InvalidTokenException, ServerAuthException
+    {
+        try
+        {
+            if (!key.isCompatible(token.getAlgorithm()) || !token.verify(key.getKey()))
+                throw new InvalidTokenException("invalid signature", proof);
+        }
+        catch (SignatureException | InvalidKeyException x)
+        {
+            // The signature is malformed, for example it has the wrong length,
+            // or the key cannot be used to verify it: the token is invalid.
+            throw new InvalidTokenException("invalid signature", proof);
+        }
+        catch (GeneralSecurityException x)
+        {
+            throw new ServerAuthException(x);
+        }
+    }
+
+    private List<String> asymmetricAlgorithms()
+    {
+        return _algorithms.stream().filter(algorithm -> !JsonWebToken.HS256.equals(algorithm)).collect(Collectors.toList());
+    }
+
+    @Override
+    public boolean secureResponse(ServletRequest req, ServletResponse res, boolean mandatory, User validatedUser) throws ServerAuthException
+    {
+        return true;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[iss=%s,aud=%s,dpop=%s,%s]", getClass().getSimpleName(), hashCode(), _issuer, _audiences, _dpop, _keyProvider);
+    }
+
+    private static class InvalidTokenException extends Exception
+    {
+        private final String error;
+        private final boolean dpop;
+
+        private InvalidTokenException(String message, boolean dpop)
+        {
+            super(message);
+            this.error = dpop ? "invalid_dpop_proof" : "invalid_token";
+            this.dpop = dpop;
+        }
+    }
+}
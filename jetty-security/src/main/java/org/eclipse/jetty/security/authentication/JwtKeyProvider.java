@@ -0,0 +1,79 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.io.IOException;
+import java.util.List;
+
+/**
+ * <p>Provides the keys that verify the signatures of JSON Web Tokens.</p>
+ *
+ * @see JwksKeyProvider
+ */
+public interface JwtKeyProvider
+{
+    /**
+     * <p>Returns the key that verifies the signature of the given token, typically
+     * the key with the token {@link JsonWebToken#getKeyId() key id}.</p>
+     *
+     * @param token the token to verify
+     * @return the key to verify the token with, or null if no key is available
+     * @throws IOException if the keys cannot be retrieved
+     */
+    JsonWebKey getKey(JsonWebToken token) throws IOException;
+
+    /**
+     * <p>Selects among the given keys the one that verifies the signature of the given token.</p>
+     * <p>The key is selected by key id if the token has one, otherwise it is
+     * the only key compatible with the token algorithm.</p>
+     *
+     * @param keys the keys to select from
+     * @param token the token to verify
+     * @return the selected key, or null if there is no suitable key
+     */
+    static JsonWebKey select(List<JsonWebKey> keys, JsonWebToken token)
+    {
+        String keyId = token.getKeyId();
+        String algorithm = token.getAlgorithm();
+        JsonWebKey result = null;
+        for (JsonWebKey key : keys)
+        {
+            if (!key.isCompatible(algorithm))
+                continue;
+            if (keyId != null)
+            {
+                if (keyId.equals(key.getKeyId()))
+                    return key;
+            }
+            else
+            {
+                // Without key id, the choice must not be ambiguous.
+                if (result != null)
+                    return null;
+                result = key;
+            }
+        }
+        return result;
+    }
+
+    /**
+     * @param keys the static keys
+     * @return a provider of the given static keys
+     */
+    static JwtKeyProvider of(JsonWebKey... keys)
+    {
+        List<JsonWebKey> list = List.of(keys);
+        return token -> select(list, token);
+    }
+}
@@ -0,0 +1,74 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.util.Map;
+import java.util.Objects;
+import java.util.stream.Stream;
+
+/**
+ * <p>Maps the claims of a verified JSON Web Token to the roles of the
+ * {@link org.eclipse.jetty.server.UserIdentity} created by {@link JwtAuthenticator}.</p>
+ * <p>Applications with custom claims implement this interface, while
+ * {@link #claim(String)} covers the common cases of a claim holding a list of
+ * roles, or a space separated string of roles such as the OAuth 2.0 {@code scope} claim.</p>
+ */
+@FunctionalInterface
+public interface JwtRoleMapper
+{
+    /**
+     * <p>A mapper that reads the roles from the {@code roles} claim,
+     * or the {@code scope} claim if the token has no {@code roles} claim.</p>
+     */
+    JwtRoleMapper DEFAULT = token ->
+    {
+        String[] roles = claim("roles").getRoles(token);
+        return roles.length > 0 ? roles : claim("scope").getRoles(token);
+    };
+
+    /**
+     * @param token the verified token
+     * @return the roles of the token subject, possibly empty
+     */
+    String[] getRoles(JsonWebToken token);
+
+    /**
+     * <p>Returns a mapper that reads the roles from the given claim.</p>
+     * <p>The claim name may be a dot separated path into nested claims,
+     * for example {@code realm_access.roles}.
+     * The claim value may be either a list of roles or a space separated string of roles.</p>
+     *
+     * @param name the claim name
+     * @return a mapper that reads the roles from the given claim
+     */
+    static JwtRoleMapper claim(String name)
+    {
+        Objects.requireNonNull(name);
+        String[] path = name.split("\\.");
+        return token ->
+        {
+            Object value = token.getClaims();
+            for (String segment : path)
+            {
+                if (!(value instanceof Map))
+                    return new String[0];
+                value = ((Map<?, ?>)value).get(segment);
+            }
+            Stream<String> roles = value instanceof String
+                ? Stream.of(((String)value).trim().split("\\s+"))
+                : JsonWebToken.asStrings(value).stream();
+            return roles.filter(role -> !role.isEmpty()).toArray(String[]::new);
+        };
+    }
+}
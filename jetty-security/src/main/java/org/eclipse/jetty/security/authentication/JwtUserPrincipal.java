@@ -0,0 +1,56 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.io.Serializable;
+import java.security.Principal;
+import java.util.Objects;
+
+/**
+ * <p>The {@link Principal} of the users authenticated by {@link JwtAuthenticator},
+ * that gives applications access to the claims of the verified token.</p>
+ */
+public class JwtUserPrincipal implements Principal, Serializable
+{
+    private static final long serialVersionUID = 1L;
+
+    private final String _name;
+    private final transient JsonWebToken _token;
+
+    public JwtUserPrincipal(String name, JsonWebToken token)
+    {
+        _name = Objects.requireNonNull(name);
+        _token = token;
+    }
+
+    @Override
+    public String getName()
+    {
+        return _name;
+    }
+
+    /**
+     * @return the verified token, or null if this principal has been deserialized
+     */
+    public JsonWebToken getToken()
+    {
+        return _token;
+    }
+
+    @Override
+    public String toString()
+    {
+        return _name;
+    }
+}
@@ -22,7 +22,6 @@ import org.eclipse.jetty.security.Authenticator;
 import org.eclipse.jetty.security.IdentityService;
 import org.eclipse.jetty.security.LoginService;
 import org.eclipse.jetty.server.Request;
-import org.eclipse.jetty.server.Response;
 import org.eclipse.jetty.server.UserIdentity;
 import org.eclipse.jetty.server.session.Session;
 import org.slf4j.Logger;
@@ -64,6 +63,8 @@ public abstract class LoginAuthenticator implements Authenticator
         {
             Request request = Request.getBaseRequest(servletRequest);
             renewSession(request, request == null ? null : request.getResponse());
+            if (request != null && !registerSession(request, user))
+                return null;
             return user;
         }
         return null;
@@ -77,6 +78,8 @@ public abstract class LoginAuthenticator implements Authenticator
             return;
 
         session.removeAttribute(Session.SESSION_CREATED_SECURE);
+        if (session instanceof Session)
+            ((Session)session).getSessionHandler().unregisterAuthenticatedSession(session);
     }
 
     @Override
@@ -114,31 +117,30 @@ public abstract class LoginAuthenticator implements Authenticator
 
         if (_renewSession && httpSession != null)
         {
-            synchronized (httpSession)
-            {
-                //if we should renew sessions, and there is an existing session that may have been seen by non-authenticated users
-                //(indicated by SESSION_SECURED not being set on the session) then we should change id
-                if (httpSession.getAttribute(Session.SESSION_CREATED_SECURE) != Boolean.TRUE)
-                {
-                    if (httpSession instanceof Session)
-                    {
-                        Session s = (Session)httpSession;
-                        String oldId = s.getId();
-                        s.renewId(request);
-                        s.setAttribute(Session.SESSION_CREATED_SECURE, Boolean.TRUE);
-                        if (s.isIdChanged() && (response instanceof Response))
-                            ((Response)response).replaceCookie(s.getSessionHandler().getSessionCookie(s, request.getContextPath(), request.isSecure()));
-                        if (LOG.isDebugEnabled())
-                            LOG.debug("renew {}->{}", oldId, s.getId());
-                    }
-                    else
-                    {
-                        LOG.warn("Unable to renew session {}", httpSession);
-                    }
-                    return httpSession;
-                }
-            }
+            //if we should renew sessions, and there is an existing session that may have been seen by non-authenticated users
+            //(indicated by SESSION_SECURED not being set on the session) then we should change id
+            if (httpSession instanceof Session)
+                return ((Session)httpSession).getSessionHandler().renewSessionOnAuthentication(request, response);
+            LOG.warn("Unable to renew session {}", httpSession);
         }
         return httpSession;
     }
+
+    /**
+     * Register the session of the given authenticated request with its
+     * {@link org.eclipse.jetty.server.session.SessionHandler}, which may
+     * deny the authentication if the user has too many concurrent sessions.
+     *
+     * @param request the authenticated request
+     * @param user the authenticated user
+     * @return false if the authentication must be denied
+     * @see org.eclipse.jetty.server.session.SessionHandler#registerAuthenticatedSession(HttpServletRequest, java.security.Principal)
+     */
+    protected boolean registerSession(HttpServletRequest request, UserIdentity user)
+    {
+        HttpSession httpSession = request.getSession(false);
+        if (httpSession instanceof Session)
+            return ((Session)httpSession).getSessionHandler().registerAuthenticatedSession(request, user.getUserPrincipal());
+        return true;
+    }
 }
@@ -0,0 +1,272 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.time.Clock;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.util.Base64;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.security.authentication.BasicAuthenticator;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.security.Constraint;
+import org.eclipse.jetty.util.security.Credential;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class RulesAuthorizationPolicyTest
+{
+    private static final String POLICY = "{" +
+        "\"default\": \"DENY\"," +
+        "\"rules\": [" +
+        "{\"name\": \"public\", \"path\": \"/public/*\", \"effect\": \"PERMIT\"}," +
+        "{\"name\": \"owner\", \"path\": \"/users/{name}\", \"user\": \"{name}\", \"effect\": \"PERMIT\"}," +
+        "{\"name\": \"admin\", \"path\": \"/users/{name}\", \"methods\": [\"GET\"], \"roles\": [\"admin\"], \"effect\": \"PERMIT\"}," +
+        "{\"name\": \"internal\", \"path\": \"/internal/*\", \"attributes\": {\"network\": \"internal\"}, \"effect\": \"PERMIT\"}," +
+        "{\"name\": \"hours\", \"path\": \"/reports/*\", \"authenticated\": true, \"time\": {\"from\": \"09:00\", \"to\": \"17:00\", \"zone\": \"UTC\"}, \"effect\": \"PERMIT\"}" +
+        "]}";
+
+    public WorkDir workDir;
+    private Path policyFile;
+    private Server server;
+    private LocalConnector connector;
+    private RulesAuthorizationPolicy policy;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        policyFile = workDir.getEmptyPathDir().resolve("policy.json");
+        Files.writeString(policyFile, POLICY, StandardCharsets.UTF_8);
+
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+
+        TestLoginService loginService = new TestLoginService("test");
+        loginService.putUser("tom", Credential.getCredential("tom"), new String[]{"user"});
+        loginService.putUser("harry", Credential.getCredential("harry"), new String[]{"user", "admin"});
+
+        policy = new RulesAuthorizationPolicy();
+        policy.setConfigPath(policyFile);
+        policy.setClock(Clock.fixed(Instant.parse("2026-10-14T10:00:00Z"), ZoneOffset.UTC));
+
+        ConstraintSecurityHandler securityHandler = new ConstraintSecurityHandler();
+        securityHandler.setLoginService(loginService);
+        securityHandler.setAuthenticator(new BasicAuthenticator());
+        securityHandler.setAuthorizationPolicy(policy);
+        Constraint constraint = new Constraint();
+        constraint.setAuthenticate(true);
+        constraint.setRoles(new String[]{"**"});
+        ConstraintMapping mapping = new ConstraintMapping();
+        mapping.setPathSpec("/reports/*");
+        mapping.setConstraint(constraint);
+        securityHandler.addConstraintMapping(mapping);
+        securityHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+            }
+        });
+
+        // Exposes the network of the request as an attribute.
+        HandlerWrapper networkHandler = new HandlerWrapper()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+            {
+                String network = request.getHeader("X-Network");
+                if (network != null)
+                    request.setAttribute("network", network);
+                super.handle(target, baseRequest, request, response);
+            }
+        };
+        networkHandler.setHandler(securityHandler);
+        server.setHandler(networkHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response request(String method, String path, String credentials, String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder();
+        request.append(method).append(" ").append(path).append(" HTTP/1.1\r\n");
+        request.append("Host: localhost\r\n");
+        if (credentials != null)
+        {
+            String encoded = Base64.getEncoder().encodeToString(credentials.getBytes(StandardCharsets.ISO_8859_1));
+            request.append("Authorization: Basic ").append(encoded).append("\r\n");
+        }
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(connector.getResponse(request.toString()));
+    }
+
+    @Test
+    public void testPathVariableRule() throws Exception
+    {
+        server.start();
+
+        assertThat(request("GET", "/public/index.html", null).getStatus(), is(HttpStatus.OK_200));
+
+        // The policy may permit the request once authenticated, so a challenge is sent.
+        HttpTester.Response response = request("GET", "/users/tom", null);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.get(HttpHeader.WWW_AUTHENTICATE), notNullValue());
+
+        assertThat(request("GET", "/users/tom", "tom:tom").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("PUT", "/users/tom", "tom:tom").getStatus(), is(HttpStatus.OK_200));
+        response = request("GET", "/users/harry", "tom:tom");
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(response.getContent(), containsString("!policy"));
+    }
+
+    @Test
+    public void testRoleRule() throws Exception
+    {
+        server.start();
+
+        assertThat(request("GET", "/users/tom", "harry:harry").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("PUT", "/users/tom", "harry:harry").getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testAttributeRule() throws Exception
+    {
+        server.start();
+
+        assertThat(request("GET", "/internal/status", null, "X-Network: internal").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("GET", "/internal/status", null, "X-Network: external").getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(request("GET", "/internal/status", "tom:tom").getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testTimeRule() throws Exception
+    {
+        server.start();
+
+        assertThat(request("GET", "/reports/daily", "tom:tom").getStatus(), is(HttpStatus.OK_200));
+
+        policy.setClock(Clock.fixed(Instant.parse("2026-10-14T20:00:00Z"), ZoneOffset.UTC));
+        assertThat(request("GET", "/reports/daily", "tom:tom").getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testNoDefaultDecision() throws Exception
+    {
+        Files.writeString(policyFile, "{\"rules\": [{\"path\": \"/private/*\", \"effect\": \"DENY\"}]}", StandardCharsets.UTF_8);
+        server.start();
+
+        assertThat(policy.getDefaultDecision(), is(AuthorizationPolicy.Decision.NOT_APPLICABLE));
+        assertThat(request("GET", "/other", null).getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("GET", "/private/data", "tom:tom").getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testYamlPolicy() throws Exception
+    {
+        Path yamlFile = policyFile.resolveSibling("policy.yaml");
+        Files.writeString(yamlFile, "" +
+            "# Owners can access their own data.\n" +
+            "default: DENY\n" +
+            "rules:\n" +
+            "  - {name: public, path: /public/*, effect: PERMIT}\n" +
+            "  - name: owner\n" +
+            "    path: /users/{name}\n" +
+            "    methods: [GET, PUT]\n" +
+            "    user: \"{name}\"\n" +
+            "    effect: PERMIT\n", StandardCharsets.UTF_8);
+        policy.setConfigPath(yamlFile);
+        server.start();
+
+        assertThat(policy.getRuleCount(), is(2));
+        assertThat(request("GET", "/public/index.html", null).getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("PUT", "/users/tom", "tom:tom").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("DELETE", "/users/tom", "tom:tom").getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(request("GET", "/users/harry", "tom:tom").getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testInvalidPolicy() throws Exception
+    {
+        Files.writeString(policyFile, "{\"rules\": [{\"path\": \"/*\", \"effect\": \"PERMIT\", \"unknown\": true}]}", StandardCharsets.UTF_8);
+
+        IllegalArgumentException x = assertThrows(IllegalArgumentException.class, policy::start);
+        assertThat(x.getMessage(), containsString("unknown"));
+    }
+
+    @Test
+    public void testHotReload() throws Exception
+    {
+        policy.setHotReload(true);
+        server.start();
+
+        assertThat(policy.getRuleCount(), is(5));
+        assertThat(request("GET", "/public/index.html", null).getStatus(), is(HttpStatus.OK_200));
+
+        // An invalid policy file retains the previous rules.
+        Thread.sleep(1001);
+        Files.writeString(policyFile, "{\"rules\": [", StandardCharsets.UTF_8);
+        Thread.sleep(2500);
+        assertThat(policy.getRuleCount(), is(5));
+
+        Files.writeString(policyFile, "{\"default\": \"DENY\", \"rules\": []}", StandardCharsets.UTF_8);
+        awaitRuleCount(0);
+        assertThat(request("GET", "/public/index.html", null).getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+    }
+
+    private void awaitRuleCount(int expected) throws InterruptedException
+    {
+        long start = System.nanoTime();
+        while (policy.getRuleCount() != expected && System.nanoTime() - start < 10_000_000_000L)
+        {
+            Thread.sleep(100);
+        }
+        assertThat(policy.getRuleCount(), is(expected));
+    }
+}
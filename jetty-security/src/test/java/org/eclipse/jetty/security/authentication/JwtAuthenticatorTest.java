@@ -0,0 +1,452 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.security.authentication;
+
+import java.io.IOException;
+import java.math.BigInteger;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.MessageDigest;
+import java.security.PrivateKey;
+import java.security.Signature;
+import java.security.interfaces.ECPublicKey;
+import java.security.interfaces.RSAPublicKey;
+import java.security.spec.ECGenParameterSpec;
+import java.util.Arrays;
+import java.util.Base64;
+import java.util.HashMap;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.UUID;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.crypto.Mac;
+import javax.crypto.spec.SecretKeySpec;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.security.ConstraintMapping;
+import org.eclipse.jetty.security.ConstraintSecurityHandler;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.security.Constraint;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.junit.jupiter.api.Assertions.assertNull;
+
+public class JwtAuthenticatorTest
+{
+    private static final String ISSUER = "https://issuer.example.com";
+    private static final String AUDIENCE = "api";
+    private static final Base64.Encoder BASE64URL = Base64.getUrlEncoder().withoutPadding();
+
+    private Server server;
+    private LocalConnector connector;
+    private JwtAuthenticator authenticator;
+    private KeyPair rsaKeyPair;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("RSA");
+        generator.initialize(2048);
+        rsaKeyPair = generator.generateKeyPair();
+
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+
+        authenticator = new JwtAuthenticator(JwtKeyProvider.of(new JsonWebKey("rsa", rsaKeyPair.getPublic())));
+        authenticator.setIssuer(ISSUER);
+        authenticator.setAudiences(AUDIENCE);
+
+        Constraint constraint = new Constraint();
+        constraint.setRoles(new String[]{"admin"});
+        constraint.setAuthenticate(true);
+        ConstraintMapping mapping = new ConstraintMapping();
+        mapping.setPathSpec("/api/*");
+        mapping.setConstraint(constraint);
+
+        ConstraintSecurityHandler securityHandler = new ConstraintSecurityHandler();
+        securityHandler.setRealmName("test");
+        securityHandler.addConstraintMapping(mapping);
+        securityHandler.setAuthenticator(authenticator);
+        securityHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                JwtUserPrincipal principal = (JwtUserPrincipal)request.getUserPrincipal();
+                response.getWriter().print("user=" + principal.getName() + " email=" + principal.getToken().getClaim("email"));
+            }
+        });
+        server.setHandler(securityHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private Map<String, Object> claims()
+    {
+        long now = TimeUnit.MILLISECONDS.toSeconds(System.currentTimeMillis());
+        Map<String, Object> claims = new HashMap<>();
+        claims.put("iss", ISSUER);
+        claims.put("aud", AUDIENCE);
+        claims.put("sub", "alice");
+        claims.put("email", "alice@example.com");
+        claims.put("roles", List.of("admin"));
+        claims.put("iat", now);
+        claims.put("exp", now + 300);
+        return claims;
+    }
+
+    private static String sign(Map<String, Object> header, Map<String, Object> claims, PrivateKey key, String algorithm) throws Exception
+    {
+        JSON json = new JSON();
+        String input = BASE64URL.encodeToString(json.toJSON(header).getBytes(StandardCharsets.UTF_8)) + "." +
+            BASE64URL.encodeToString(json.toJSON(claims).getBytes(StandardCharsets.UTF_8));
+        Signature signature = Signature.getInstance(algorithm);
+        signature.initSign(key);
+        signature.update(input.getBytes(StandardCharsets.US_ASCII));
+        return input + "." + BASE64URL.encodeToString(signature.sign());
+    }
+
+    private String rs256(Map<String, Object> claims) throws Exception
+    {
+        return sign(Map.of("alg", "RS256", "kid", "rsa"), claims, rsaKeyPair.getPrivate(), "SHA256withRSA");
+    }
+
+    private HttpTester.Response get(String path, String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(connector.getResponse(request.toString()));
+    }
+
+    @Test
+    public void testValidToken() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = get("/api/data", "Authorization: Bearer " + rs256(claims()));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("user=alice email=alice@example.com"));
+    }
+
+    @Test
+    public void testMissingToken() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = get("/api/data");
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.get(HttpHeader.WWW_AUTHENTICATE), is("Bearer realm=\"test\""));
+    }
+
+    @Test
+    public void testInvalidTokens() throws Exception
+    {
+        server.start();
+
+        Map<String, Object> expired = claims();
+        expired.put("exp", (Long)expired.get("iat") - 120);
+        Map<String, Object> otherIssuer = claims();
+        otherIssuer.put("iss", "https://other.example.com");
+        Map<String, Object> otherAudience = claims();
+        otherAudience.put("aud", List.of("other"));
+        Map<String, Object> notAdmin = claims();
+        notAdmin.put("roles", List.of("user"));
+
+        assertInvalid(rs256(expired), "token expired");
+        assertInvalid(rs256(otherIssuer), "invalid issuer");
+        assertInvalid(rs256(otherAudience), "invalid audience");
+        assertInvalid("not.a.token", "malformed token");
+
+        // Tamper with the claims.
+        String token = rs256(claims());
+        String[] parts = token.split("\\.");
+        Map<String, Object> tampered = claims();
+        tampered.put("sub", "mallory");
+        String forged = parts[0] + "." + BASE64URL.encodeToString(new JSON().toJSON(tampered).getBytes(StandardCharsets.UTF_8)) + "." + parts[2];
+        assertInvalid(forged, "invalid signature");
+
+        // A signature with the wrong length cannot be verified.
+        String truncated = parts[0] + "." + parts[1] + "." + BASE64URL.encodeToString(new byte[16]);
+        assertInvalid(truncated, "invalid signature");
+
+        // A valid token without the required role is forbidden.
+        assertThat(get("/api/data", "Authorization: Bearer " + rs256(notAdmin)).getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    private void assertInvalid(String token, String description) throws Exception
+    {
+        HttpTester.Response response = get("/api/data", "Authorization: Bearer " + token);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        String challenge = response.get(HttpHeader.WWW_AUTHENTICATE);
+        assertThat(challenge, containsString("error=\"invalid_token\""));
+        assertThat(challenge, containsString("error_description=\"" + description + "\""));
+    }
+
+    @Test
+    public void testClockSkew() throws Exception
+    {
+        server.start();
+
+        Map<String, Object> claims = claims();
+        claims.put("exp", (Long)claims.get("iat") - 10);
+        assertThat(get("/api/data", "Authorization: Bearer " + rs256(claims)).getStatus(), is(HttpStatus.OK_200));
+
+        authenticator.setClockSkew(0);
+        assertThat(get("/api/data", "Authorization: Bearer " + rs256(claims)).getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+    }
+
+    @Test
+    public void testAlgorithmConfusion() throws Exception
+    {
+        server.start();
+
+        // An HS256 token signed with the RSA public key as HMAC secret must be rejected.
+        JSON json = new JSON();
+        String input = BASE64URL.encodeToString(json.toJSON(Map.of("alg", "HS256", "kid", "rsa")).getBytes(StandardCharsets.UTF_8)) + "." +
+            BASE64URL.encodeToString(json.toJSON(claims()).getBytes(StandardCharsets.UTF_8));
+        Mac mac = Mac.getInstance("HmacSHA256");
+        mac.init(new SecretKeySpec(rsaKeyPair.getPublic().getEncoded(), "HmacSHA256"));
+        String token = input + "." + BASE64URL.encodeToString(mac.doFinal(input.getBytes(StandardCharsets.US_ASCII)));
+
+        assertThat(get("/api/data", "Authorization: Bearer " + token).getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+
+        // An unsigned token must be rejected.
+        String none = BASE64URL.encodeToString(json.toJSON(Map.of("alg", "none")).getBytes(StandardCharsets.UTF_8)) + "." + input.split("\\.")[1] + ".";
+        assertInvalid(none, "unsupported algorithm");
+    }
+
+    @Test
+    public void testES256AndHS256() throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("EC");
+        generator.initialize(new ECGenParameterSpec("secp256r1"));
+        KeyPair ecKeyPair = generator.generateKeyPair();
+        byte[] secret = "0123456789abcdef0123456789abcdef".getBytes(StandardCharsets.US_ASCII);
+        authenticator.setKeyProvider(JwtKeyProvider.of(new JsonWebKey("ec", ecKeyPair.getPublic()), JsonWebKey.hmac("hmac", secret)));
+        server.start();
+
+        String es256 = sign(Map.of("alg", "ES256", "kid", "ec"), claims(), ecKeyPair.getPrivate(), "SHA256withECDSAinP1363Format");
+        assertThat(get("/api/data", "Authorization: Bearer " + es256).getStatus(), is(HttpStatus.OK_200));
+
+        JSON json = new JSON();
+        String input = BASE64URL.encodeToString(json.toJSON(Map.of("alg", "HS256", "kid", "hmac")).getBytes(StandardCharsets.UTF_8)) + "." +
+            BASE64URL.encodeToString(json.toJSON(claims()).getBytes(StandardCharsets.UTF_8));
+        Mac mac = Mac.getInstance("HmacSHA256");
+        mac.init(new SecretKeySpec(secret, "HmacSHA256"));
+        String hs256 = input + "." + BASE64URL.encodeToString(mac.doFinal(input.getBytes(StandardCharsets.US_ASCII)));
+        assertThat(get("/api/data", "Authorization: Bearer " + hs256).getStatus(), is(HttpStatus.OK_200));
+
+        authenticator.setAlgorithms("ES256");
+        assertThat(get("/api/data", "Authorization: Bearer " + hs256).getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+    }
+
+    @Test
+    public void testRoleMapper() throws Exception
+    {
+        authenticator.setRoleMapper(JwtRoleMapper.claim("realm_access.roles"));
+        server.start();
+
+        Map<String, Object> claims = claims();
+        claims.remove("roles");
+        assertThat(get("/api/data", "Authorization: Bearer " + rs256(claims)).getStatus(), is(HttpStatus.FORBIDDEN_403));
+
+        claims.put("realm_access", Map.of("roles", List.of("user", "admin")));
+        assertThat(get("/api/data", "Authorization: Bearer " + rs256(claims)).getStatus(), is(HttpStatus.OK_200));
+
+        claims.remove("realm_access");
+        claims.put("scope", "read admin");
+        assertThat(JwtRoleMapper.DEFAULT.getRoles(JsonWebToken.parse(rs256(claims))), is(new String[]{"read", "admin"}));
+    }
+
+    @Test
+    public void testJwksKeyRotation() throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("RSA");
+        generator.initialize(2048);
+        KeyPair rotated = generator.generateKeyPair();
+
+        AtomicInteger fetches = new AtomicInteger();
+        List<String> jwks = Arrays.asList(jwks("rsa", rsaKeyPair), jwks("rotated", rotated));
+        JwksKeyProvider keyProvider = new JwksKeyProvider(URI.create("https://issuer.example.com/jwks"))
+        {
+            @Override
+            protected String fetch(URI uri)
+            {
+                return jwks.get(Math.min(fetches.getAndIncrement(), 1));
+            }
+        };
+        keyProvider.setMinRefreshInterval(0);
+
+        JsonWebToken token = JsonWebToken.parse(rs256(claims()));
+        assertThat(keyProvider.getKey(token).getKeyId(), is("rsa"));
+        // The keys are cached.
+        assertThat(keyProvider.getKey(token).getKeyId(), is("rsa"));
+        assertThat(fetches.get(), is(1));
+
+        // A token with an unknown key id triggers a refresh.
+        JsonWebToken rotatedToken = JsonWebToken.parse(sign(Map.of("alg", "RS256", "kid", "rotated"), claims(), rotated.getPrivate(), "SHA256withRSA"));
+        JsonWebKey key = keyProvider.getKey(rotatedToken);
+        assertThat(key.getKeyId(), is("rotated"));
+        assertThat(rotatedToken.verify(key.getKey()), is(true));
+        assertThat(fetches.get(), is(2));
+
+        // Refreshes for unknown key ids are rate limited.
+        keyProvider.setMinRefreshInterval(TimeUnit.MINUTES.toMillis(1));
+        JsonWebToken unknown = JsonWebToken.parse(sign(Map.of("alg", "RS256", "kid", "unknown"), claims(), rotated.getPrivate(), "SHA256withRSA"));
+        assertNull(keyProvider.getKey(unknown));
+        assertNull(keyProvider.getKey(unknown));
+        assertThat(fetches.get(), is(2));
+    }
+
+    private static String jwks(String kid, KeyPair keyPair)
+    {
+        RSAPublicKey publicKey = (RSAPublicKey)keyPair.getPublic();
+        Map<String, Object> jwk = new LinkedHashMap<>();
+        jwk.put("kty", "RSA");
+        jwk.put("kid", kid);
+        jwk.put("use", "sig");
+        jwk.put("n", BASE64URL.encodeToString(unsigned(publicKey.getModulus())));
+        jwk.put("e", BASE64URL.encodeToString(unsigned(publicKey.getPublicExponent())));
+        return new JSON().toJSON(Map.of("keys", List.of(jwk)));
+    }
+
+    private static byte[] unsigned(BigInteger value)
+    {
+        byte[] bytes = value.toByteArray();
+        return bytes[0] == 0 ? Arrays.copyOfRange(bytes, 1, bytes.length) : bytes;
+    }
+
+    private static byte[] coordinate(BigInteger value)
+    {
+        byte[] bytes = unsigned(value);
+        byte[] result = new byte[32];
+        System.arraycopy(bytes, 0, result, result.length - bytes.length, bytes.length);
+        return result;
+    }
+
+    @Test
+    public void testDPoP() throws Exception
+    {
+        authenticator.setDPoP(JwtAuthenticator.DPoP.ENABLED);
+        server.start();
+
+        KeyPairGenerator generator = KeyPairGenerator.getInstance("EC");
+        generator.initialize(new ECGenParameterSpec("secp256r1"));
+        KeyPair clientKeyPair = generator.generateKeyPair();
+        ECPublicKey publicKey = (ECPublicKey)clientKeyPair.getPublic();
+        Map<String, Object> jwk = new LinkedHashMap<>();
+        jwk.put("kty", "EC");
+        jwk.put("crv", "P-256");
+        jwk.put("x", BASE64URL.encodeToString(coordinate(publicKey.getW().getAffineX())));
+        jwk.put("y", BASE64URL.encodeToString(coordinate(publicKey.getW().getAffineY())));
+        String thumbprint = JsonWebKey.from(jwk).getThumbprint();
+
+        Map<String, Object> claims = claims();
+        claims.put("cnf", Map.of("jkt", thumbprint));
+        String token = rs256(claims);
+
+        String proof = proof(clientKeyPair, jwk, "GET", "http://localhost/api/data", token);
+        assertThat(get("/api/data", "Authorization: DPoP " + token, "DPoP: " + proof).getStatus(), is(HttpStatus.OK_200));
+
+        // A proof cannot be replayed.
+        HttpTester.Response response = get("/api/data", "Authorization: DPoP " + token, "DPoP: " + proof);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.getValuesList(HttpHeader.WWW_AUTHENTICATE).toString(), containsString("error_description=\"proof replayed\""));
+
+        // A proof is bound to the request URI.
+        String otherProof = proof(clientKeyPair, jwk, "GET", "http://localhost/api/other", token);
+        assertThat(get("/api/data", "Authorization: DPoP " + token, "DPoP: " + otherProof).getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+
+        // A DPoP-bound token cannot be used as a bearer token.
+        response = get("/api/data", "Authorization: Bearer " + token);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.getValuesList(HttpHeader.WWW_AUTHENTICATE).toString(), containsString("DPoP-bound token required"));
+
+        // A proof made with another key is rejected.
+        KeyPair otherKeyPair = generator.generateKeyPair();
+        ECPublicKey otherPublicKey = (ECPublicKey)otherKeyPair.getPublic();
+        Map<String, Object> otherJwk = new LinkedHashMap<>(jwk);
+        otherJwk.put("x", BASE64URL.encodeToString(coordinate(otherPublicKey.getW().getAffineX())));
+        otherJwk.put("y", BASE64URL.encodeToString(coordinate(otherPublicKey.getW().getAffineY())));
+        String wrongKeyProof = proof(otherKeyPair, otherJwk, "GET", "http://localhost/api/data", token);
+        response = get("/api/data", "Authorization: DPoP " + token, "DPoP: " + wrongKeyProof);
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.getValuesList(HttpHeader.WWW_AUTHENTICATE).toString(), not(containsString("Bearer realm=\"test\", error")));
+    }
+
+    private static String proof(KeyPair keyPair, Map<String, Object> jwk, String method, String uri, String token) throws Exception
+    {
+        Map<String, Object> header = Map.of("typ", "dpop+jwt", "alg", "ES256", "jwk", jwk);
+        Map<String, Object> claims = new HashMap<>();
+        claims.put("jti", UUID.randomUUID().toString());
+        claims.put("htm", method);
+        claims.put("htu", uri);
+        claims.put("iat", TimeUnit.MILLISECONDS.toSeconds(System.currentTimeMillis()));
+        byte[] hash = MessageDigest.getInstance("SHA-256").digest(token.getBytes(StandardCharsets.US_ASCII));
+        claims.put("ath", BASE64URL.encodeToString(hash));
+        return sign(header, claims, keyPair.getPrivate(), "SHA256withECDSAinP1363Format");
+    }
+
+    @Test
+    public void testInitParameters() throws Exception
+    {
+        ConstraintSecurityHandler securityHandler = (ConstraintSecurityHandler)server.getHandler();
+        securityHandler.setAuthenticator(null);
+        securityHandler.setAuthMethod(Constraint.__JWT_AUTH);
+        securityHandler.setInitParameter(JwtAuthenticator.__JWT_ISSUER, "https://other.example.com");
+        securityHandler.setInitParameter(JwtAuthenticator.__JWT_AUDIENCES, "api, other");
+        securityHandler.setInitParameter(JwtAuthenticator.__JWT_JWKS_URI, "https://other.example.com/jwks");
+        securityHandler.setInitParameter(JwtAuthenticator.__JWT_DPOP, "required");
+        server.start();
+
+        JwtAuthenticator jwt = (JwtAuthenticator)securityHandler.getAuthenticator();
+        assertThat(jwt.getIssuer(), is("https://other.example.com"));
+        assertThat(jwt.getAudiences(), contains("api", "other"));
+        assertThat(((JwksKeyProvider)jwt.getKeyProvider()).getURI(), is(URI.create("https://other.example.com/jwks")));
+        assertThat(jwt.getDPoP(), is(JwtAuthenticator.DPoP.REQUIRED));
+
+        HttpTester.Response response = get("/api/data");
+        assertThat(response.getStatus(), is(HttpStatus.UNAUTHORIZED_401));
+        assertThat(response.get(HttpHeader.WWW_AUTHENTICATE), is("DPoP realm=\"test\", algs=\"RS256 ES256\""));
+    }
+}
@@ -0,0 +1,27 @@
+[description]
+Partitions the server threads between virtual hosts or contexts.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-bulkhead.xml
+
+[ini-template]
+## The max threads handling requests that match no partition (0 for no limit)
+#jetty.bulkhead.default.maxConcurrency=0
+
+## The max requests waiting for a thread of the default partition
+#jetty.bulkhead.default.maxQueueSize=0
+
+## The max milliseconds a request waits for a thread of the default partition
+#jetty.bulkhead.default.queueTimeout=30000
+
+## The response status of rejected requests
+#jetty.bulkhead.rejectStatus=503
+
+## The Retry-After seconds of rejected requests (negative for no header)
+#jetty.bulkhead.retryAfter=1
@@ -36,3 +36,7 @@ etc/jetty-bytebufferpool.xml
 
 ## Maximum direct memory retained whilst in use by the pool (0 for heuristic, -1 for unlimited, -2 for no retained).
 #jetty.byteBufferPool.retainedDirectMemory=0
+
+## Strategy used to look up retained ByteBuffers (FIRST, RANDOM, THREAD_ID, ROUND_ROBIN, STRIPED).
+## STRIPED reduces contention on machines with many cores.
+#jetty.byteBufferPool.retainedStrategy=THREAD_ID
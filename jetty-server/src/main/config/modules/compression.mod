@@ -0,0 +1,35 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables CompressionHandler for dynamic response compression for the entire server,
+using the content codings negotiated from the Accept-Encoding request header.
+Content codings other than gzip are enabled by their own modules (for example zstd).
+
+[tags]
+server
+handler
+
+[depend]
+server
+
+[xml]
+etc/jetty-compression.xml
+
+[ini-template]
+## Comma separated list of content codings, in order of server preference
+# jetty.compression.encodingList=zstd,gzip
+
+## Minimum content length after which compression is enabled
+# jetty.compression.minCompressSize=32
+
+## Gzip compression level (-1 for default)
+# jetty.compression.gzip.level=-1
+
+## Comma separated list of included HTTP methods
+# jetty.compression.includedMethodList=GET
+
+## Comma separated list of included MIME types (if not empty, only these are compressed)
+# jetty.compression.includedMimeTypeList=
+
+## Comma separated list of excluded MIME types
+# jetty.compression.excludedMimeTypeList=
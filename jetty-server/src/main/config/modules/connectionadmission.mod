@@ -0,0 +1,36 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables server-wide admission control of connections by remote IP address and subnet.
+
+[tags]
+connector
+
+[depend]
+server
+
+[xml]
+etc/jetty-connectionadmission.xml
+
+[ini-template]
+## The max number of connections per remote IP address (-1 for no limit)
+#jetty.connectionadmission.maxConnectionsPerAddress=-1
+
+## The max number of connections per remote subnet (-1 for no limit)
+#jetty.connectionadmission.maxConnectionsPerSubnet=-1
+
+## The prefix length of IPv4 subnets
+#jetty.connectionadmission.ipv4SubnetPrefixLength=24
+
+## The prefix length of IPv6 subnets
+#jetty.connectionadmission.ipv6SubnetPrefixLength=64
+
+## The max number of connections a remote IP address may open
+## within the accept rate period before being greylisted (-1 for no limit)
+#jetty.connectionadmission.maxAcceptRatePerAddress=-1
+
+## The period (in milliseconds) over which the per address accept rate is measured
+#jetty.connectionadmission.acceptRatePeriod=1000
+
+## The period (in milliseconds) during which greylisted addresses are rejected
+#jetty.connectionadmission.greylistPeriod=60000
@@ -0,0 +1,21 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables a per-connection journal of lifecycle events, for diagnostic purposes.
+
+[tags]
+connector
+
+[depend]
+server
+
+[xml]
+etc/jetty-connectionjournal.xml
+
+[ini-template]
+
+## The max number of events recorded for each connection
+#jetty.connectionjournal.maxEvents=32
+
+## The max number of journals of closed connections that are retained
+#jetty.connectionjournal.maxClosedConnections=64
@@ -0,0 +1,23 @@
+[description]
+Verifies that the response content matches the declared Content-Type,
+checking the magic bytes of images and that JSON and text are UTF-8,
+and sends X-Content-Type-Options: nosniff on checked responses.
+
+[tags]
+server
+
+[depend]
+server
+
+[before]
+gzip
+
+[xml]
+etc/jetty-content-type-enforcement.xml
+
+[ini-template]
+## What is done on violations: LOG or REJECT (replace with a 500 response)
+#jetty.contentTypeEnforcement.mode=LOG
+
+## Whether checked responses are sent with X-Content-Type-Options: nosniff
+#jetty.contentTypeEnforcement.noSniff=true
@@ -0,0 +1,23 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables DecompressionHandler to decode compressed request content for the entire server,
+using the content codings specified by the Content-Encoding request header.
+Content codings other than gzip and deflate are enabled by their own modules (for example zstd).
+
+[tags]
+server
+handler
+
+[depend]
+server
+
+[xml]
+etc/jetty-decompression.xml
+
+[ini-template]
+## Comma separated list of content codings to decode
+# jetty.decompression.encodingList=gzip,deflate
+
+## Max decompressed size of request content in bytes (-1 for no limit)
+# jetty.decompression.maxDecompressedSize=67108864
@@ -0,0 +1,23 @@
+[description]
+Injects faults in the handling of requests, such as latency, aborted
+connections, error responses, truncated and corrupted responses,
+to run chaos experiments against the clients of the server.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-fault-injection.xml
+
+[ini-template]
+## Whether faults are injected; can also be changed at runtime via JMX
+#jetty.faultInjection.enabled=false
+
+## Comma separated rules of the form fault[;name=value]*, where fault is one of
+## LATENCY, ABORT, ERROR, TRUNCATE, CORRUPT_CHUNKED and the parameters are
+## path, header, probability, latency, status and bytes, for example:
+## LATENCY;path=/api/*;probability=0.1;latency=2000,ERROR;header=X-Chaos:on;status=503
+#jetty.faultInjection.rules=
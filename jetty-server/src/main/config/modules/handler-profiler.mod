@@ -0,0 +1,14 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Records the CPU time and the allocated bytes of each handler.
+The profiles are available via JMX, the server dump and the statistics handler.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-handler-profiler.xml
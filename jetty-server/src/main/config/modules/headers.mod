@@ -0,0 +1,33 @@
+[description]
+Post-processes the response headers, adding security headers such as
+Strict-Transport-Security or Content-Security-Policy.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-headers.xml
+
+[ini-template]
+## The Strict-Transport-Security header, only sent for secure requests (empty to disable)
+#jetty.headers.strictTransportSecurity=max-age=31536000; includeSubDomains
+
+## The Content-Security-Policy header (empty to disable).
+## The {nonce} placeholder is replaced by a per-request nonce, also available
+## to the application as the org.eclipse.jetty.server.handler.HeadersHandler.cspNonce attribute.
+#jetty.headers.contentSecurityPolicy=
+
+## The X-Content-Type-Options header (empty to disable)
+#jetty.headers.contentTypeOptions=nosniff
+
+## The Referrer-Policy header (empty to disable)
+#jetty.headers.referrerPolicy=strict-origin-when-cross-origin
+
+## The Cross-Origin-Opener-Policy header (empty to disable)
+#jetty.headers.crossOriginOpenerPolicy=same-origin
+
+## The Cross-Origin-Embedder-Policy header (empty to disable)
+#jetty.headers.crossOriginEmbedderPolicy=
@@ -53,4 +53,9 @@ etc/jetty-http-forwarded.xml
 
 ## The name of the obsolete forwarded SSL cipher HTTP header.
 # jetty.httpConfig.forwardedCipherSuiteHeader=Proxy-auth-cert
+
+## Comma separated addresses, CIDR blocks or ranges of the trusted proxies.
+## When set, forwarded headers are only processed if sent by a trusted proxy,
+## and only the hops added by trusted proxies are used.
+# jetty.httpConfig.forwardedTrustedProxies=10.0.0.0/8,192.168.0.0/16
 # end::documentation[]
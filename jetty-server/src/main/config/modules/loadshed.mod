@@ -0,0 +1,39 @@
+[description]
+Adaptively sheds requests when the server is overloaded.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-loadshed.xml
+
+[ini-template]
+## The milliseconds between load evaluations
+#jetty.loadshed.evaluationPeriod=1000
+
+## The average queue latency in milliseconds above which the server is overloaded (0 to ignore)
+#jetty.loadshed.maxQueueLatency=100
+
+## The average service time in milliseconds above which the server is overloaded (0 to ignore)
+#jetty.loadshed.maxServiceTime=0
+
+## The thread pool queue size above which the server is overloaded (0 to ignore)
+#jetty.loadshed.maxQueueSize=0
+
+## The shed ratio increment when the server is overloaded
+#jetty.loadshed.increaseStep=0.1
+
+## The shed ratio decrement when the server is not overloaded
+#jetty.loadshed.decreaseStep=0.05
+
+## The max ratio of shed requests (at most 0.5 never sheds authenticated requests)
+#jetty.loadshed.maxShedRatio=0.9
+
+## The response status of shed requests
+#jetty.loadshed.rejectStatus=503
+
+## The Retry-After seconds of shed requests (negative for no header)
+#jetty.loadshed.retryAfter=1
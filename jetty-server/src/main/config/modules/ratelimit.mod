@@ -0,0 +1,24 @@
+[description]
+Limits the rate of requests per remote IP address.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-ratelimit.xml
+
+[ini-template]
+## The rate limiting algorithm, either TOKEN_BUCKET or LEAKY_BUCKET
+#jetty.ratelimit.algorithm=TOKEN_BUCKET
+
+## The number of requests per second allowed per remote IP
+#jetty.ratelimit.rate=10
+
+## The max burst (TOKEN_BUCKET) or max delayed requests (LEAKY_BUCKET) per remote IP
+#jetty.ratelimit.burst=10
+
+## The max number of remote IPs tracked before idle ones are evicted
+#jetty.ratelimit.maxKeys=10000
@@ -0,0 +1,19 @@
+[description]
+Enforces a deadline on the total duration of requests.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-request-deadline.xml
+
+[ini-template]
+## The max duration in ms of a request, from the request headers to the response completion
+#jetty.requestDeadline.deadline=30000
+
+## The grace period in ms given to committed responses that are still writing content
+## when the deadline expires (0 to abort them immediately)
+#jetty.requestDeadline.gracePeriod=0
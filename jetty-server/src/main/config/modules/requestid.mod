@@ -0,0 +1,32 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Assigns a unique ID to each request, echoed in a response header and available to logging.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-requestid.xml
+
+[ini-template]
+## The request ID generator: ULID, UUIDv7, UUID or the class name of a RequestIdHandler.Generator
+#jetty.requestid.generator=ULID
+
+## The request header that may carry the request ID assigned upstream
+#jetty.requestid.requestHeader=X-Request-Id
+
+## Whether the request ID is taken from the request header, for example when behind a trusted proxy
+#jetty.requestid.trustRequestHeader=false
+
+## The max length of a request ID taken from the request header
+#jetty.requestid.maxRequestIdLength=128
+
+## The response header that echoes the request ID
+#jetty.requestid.responseHeader=X-Request-Id
+
+## The SLF4J MDC key of the request ID
+#jetty.requestid.mdcKey=requestId
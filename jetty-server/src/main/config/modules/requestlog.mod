@@ -38,4 +38,13 @@ logs/
 
 ## The timezone of the log file name.
 # jetty.requestlog.timezone=GMT
+
+## The max number of entries queued for writing.
+# jetty.requestlog.queueCapacity=1024
+
+## The max number of entries written before the log file is flushed.
+# jetty.requestlog.batchSize=64
+
+## What to do when the queue is full: BLOCK, DROP_OLDEST or DROP_NEWEST.
+# jetty.requestlog.overflowPolicy=DROP_NEWEST
 # end::documentation[]
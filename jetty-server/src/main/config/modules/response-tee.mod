@@ -0,0 +1,25 @@
+[description]
+Records a copy of the response content, for example for compliance
+auditing of API responses, to one file per response.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-response-tee.xml
+
+[ini-template]
+## The directory, relative to jetty.base, where the responses are recorded
+#jetty.responseTee.directory=logs/responses
+
+## The max number of content bytes recorded for each response (-1 for unlimited)
+#jetty.responseTee.maxContentLength=65536
+
+## Comma separated list of the mime types that are recorded (empty for all)
+#jetty.responseTee.includeMimeTypes=
+
+## Comma separated list of the mime types that are not recorded
+#jetty.responseTee.excludeMimeTypes=
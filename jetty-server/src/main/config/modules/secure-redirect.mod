@@ -15,3 +15,22 @@ etc/jetty-secure-redirect.xml
 [ini-template]
 ## The redirect code to use in the response.
 # jetty.secureredirect.code=302
+
+## The max age in seconds of the Strict-Transport-Security header of secure responses, -1 for no header.
+# jetty.secureredirect.hsts.maxAge=-1
+
+## Whether the Strict-Transport-Security header has the includeSubDomains directive.
+# jetty.secureredirect.hsts.includeSubDomains=false
+
+## Whether the Strict-Transport-Security header has the preload directive.
+## Preloading requires includeSubDomains and a max age of at least 31536000.
+# jetty.secureredirect.hsts.preload=false
+
+## Whether non-idempotent requests, such as POST, are rejected with 403 instead of redirected.
+# jetty.secureredirect.rejectNonIdempotentMethods=false
+
+## Comma separated hosts that are not redirected, for example *.internal.example.com.
+# jetty.secureredirect.excludedHosts=
+
+## Comma separated path specs of the requests that are not redirected.
+# jetty.secureredirect.excludedPaths=/.well-known/acme-challenge/*
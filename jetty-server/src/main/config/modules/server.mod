@@ -60,6 +60,21 @@ etc/jetty.xml
 ## Maximum number of error dispatches to prevent looping
 # jetty.httpConfig.maxErrorDispatches=10
 
+## Maximum number of consecutive pipelined HTTP/1.1 requests (-1 for no limit)
+# jetty.httpConfig.maxPipelinedRequests=-1
+
+## Maximum number of requests served by a persistent HTTP/1 connection (-1 for no limit)
+# jetty.httpConfig.maxRequestsPerConnection=-1
+
+## Maximum age (in milliseconds) of a persistent HTTP/1 connection (-1 for no limit)
+# jetty.httpConfig.maxConnectionAge=-1
+
+## Maximum random amount (in milliseconds) subtracted from the maximum connection age
+# jetty.httpConfig.maxConnectionAgeJitter=0
+
+## Whether to send the Keep-Alive: header on persistent HTTP/1 connections
+# jetty.httpConfig.sendKeepAliveHeader=false
+
 ## Relative Redirect Locations allowed
 # jetty.httpConfig.relativeRedirectAllowed=false
 
@@ -69,17 +84,26 @@ etc/jetty.xml
 # end::documentation-http-config[]
 
 # tag::documentation-server-compliance[]
-## HTTP Compliance: RFC7230, RFC7230_LEGACY, RFC2616, RFC2616_LEGACY, LEGACY
+## HTTP Compliance: RFC7230, RFC7230_LEGACY, RFC2616, RFC2616_LEGACY, LEGACY, PARANOID
 # jetty.httpConfig.compliance=RFC7230
 
 ## URI Compliance: DEFAULT, LEGACY, RFC3986, RFC3986_UNAMBIGUOUS, UNSAFE
 # jetty.httpConfig.uriCompliance=DEFAULT
 
-## Cookie compliance mode for parsing request Cookie headers: RFC6265_STRICT, RFC6265, RFC6265_LEGACY, RFC2965, RFC2965_LEGACY
+## Cookie compliance mode for parsing request Cookie headers: RFC6265_STRICT, RFC6265, RFC6265BIS, RFC6265_LEGACY, RFC2965, RFC2965_LEGACY
 # jetty.httpConfig.requestCookieCompliance=RFC6265
 
-## Cookie compliance mode for generating response Set-Cookie: RFC2965, RFC6265
+## Cookie compliance mode for generating response Set-Cookie: RFC2965, RFC6265, RFC6265BIS (checks __Secure- and __Host- prefixes)
 # jetty.httpConfig.responseCookieCompliance=RFC6265
+
+## Default SameSite attribute of response cookies: None, Lax, Strict or empty for no default
+# jetty.httpConfig.cookieSameSiteDefault=
+
+## Whether response cookies to secure requests are made Secure by default
+# jetty.httpConfig.cookieSecureDefault=false
+
+## Whether Secure response cookies with SameSite=None are made Partitioned by default
+# jetty.httpConfig.cookiePartitionedDefault=false
 # end::documentation-server-compliance[]
 
 ## multipart/form-data compliance mode of: LEGACY(slow), RFC7578(fast)
@@ -0,0 +1,28 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables the broadcast of session events (creation, invalidation and attribute
+changes) to the other nodes of the cluster using UDP IP multicast, so that
+they evict their cached copy of the session immediately.
+
+[tags]
+session
+
+[depends]
+sessions
+
+[xml]
+etc/sessions/session-events-multicast.xml
+
+[ini-template]
+## The multicast group address
+#jetty.session.events.multicast.group=239.255.43.21
+
+## The multicast port
+#jetty.session.events.multicast.port=45700
+
+## The time to live of the multicast packets
+#jetty.session.events.multicast.timeToLive=1
+
+## The name of the network interface to use, for example eth0
+#jetty.session.events.multicast.networkInterface=
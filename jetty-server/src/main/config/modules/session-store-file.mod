@@ -23,3 +23,11 @@ jetty.session.file.storeDir=${jetty.base}/sessions
 #jetty.session.file.deleteUnrestorableFiles=false
 #jetty.session.gracePeriod.seconds=3600
 #jetty.session.savePeriod.seconds=0
+## Name of the SessionDataCodec that serializes the session attributes
+#jetty.session.serialization.codec=java
+## Content coding used to compress the session attributes, such as gzip
+#jetty.session.serialization.compression=
+## Min serialized size in bytes of the session attributes to compress them
+#jetty.session.serialization.compressionThreshold=1024
+## Serialized size in bytes above which a warning is logged, -1 to never warn
+#jetty.session.serialization.warnSize=-1
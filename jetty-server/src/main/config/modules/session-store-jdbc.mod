@@ -32,6 +32,22 @@ db-connection-type?=datasource
 #jetty.session.jdbc.longType=
 #jetty.session.jdbc.stringType=
 
+## Max number of orphaned sessions deleted per transaction, 0 for no limit
+#jetty.session.jdbc.scavengeBatchSize=0
+## Number of session updates written in a batch, 0 to write immediately
+#jetty.session.jdbc.writeBatchSize=0
+## java.sql.Connection isolation level of the batch transactions, -1 for the default
+#jetty.session.jdbc.transactionIsolation=-1
+
+## Name of the SessionDataCodec that serializes the session attributes
+#jetty.session.serialization.codec=java
+## Content coding used to compress the session attributes, such as gzip
+#jetty.session.serialization.compression=
+## Min serialized size in bytes of the session attributes to compress them
+#jetty.session.serialization.compressionThreshold=1024
+## Serialized size in bytes above which a warning is logged, -1 to never warn
+#jetty.session.serialization.warnSize=-1
+
 ## Connection type:Datasource
 db-connection-type=datasource
 #jetty.session.jdbc.datasourceName=/jdbc/sessions
@@ -0,0 +1,19 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Enables the periodic sampling of the TCP telemetry of connections,
+such as the round trip time, the retransmissions and the congestion window.
+
+[tags]
+connector
+
+[depend]
+server
+
+[xml]
+etc/jetty-socket-telemetry.xml
+
+[ini-template]
+
+## The period between samples in ms
+#jetty.socketTelemetry.samplePeriod=5000
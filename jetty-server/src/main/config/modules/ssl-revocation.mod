@@ -0,0 +1,52 @@
+[description]
+Enables checking the revocation status of TLS peer certificates,
+via OCSP (with stapling and response caching) and periodically reloaded CRLs.
+
+[tags]
+connector
+ssl
+
+[depend]
+ssl
+
+[xml]
+etc/jetty-ssl-context-revocation.xml
+
+[ini-template]
+# tag::documentation[]
+## The policy when the revocation status cannot be determined, either HARD_FAIL or SOFT_FAIL.
+# jetty.sslContext.revocation.failurePolicy=HARD_FAIL
+
+## Whether OCSP is used; when false only CRLs are used.
+# jetty.sslContext.revocation.enableOCSP=true
+
+## The OCSP responder URL that overrides the one named in the certificates.
+# jetty.sslContext.revocation.ocspResponder=
+
+## The connect and read timeout of OCSP queries, in milliseconds.
+# jetty.sslContext.revocation.ocspTimeout=5000
+
+## The time OCSP responses are cached for, in milliseconds.
+# jetty.sslContext.revocation.ocspCacheTime=3600000
+
+## The max number of cached OCSP responses.
+# jetty.sslContext.revocation.maxOcspCacheEntries=1024
+
+## Whether CRLs are preferred over OCSP.
+# jetty.sslContext.revocation.preferCrls=false
+
+## Whether only the revocation status of the peer certificate is checked.
+# jetty.sslContext.revocation.onlyEndEntity=false
+
+## Whether must-staple server certificates require a stapled OCSP response.
+# jetty.sslContext.revocation.enforceMustStaple=true
+
+## Whether the server staples OCSP responses to its certificates.
+# jetty.sslContext.revocation.stapling=false
+
+## The path or URL of the certificate revocation lists.
+# jetty.sslContext.revocation.crlPath=
+
+## The interval between reloads of the certificate revocation lists, in milliseconds.
+# jetty.sslContext.revocation.crlRefreshInterval=0
+# end::documentation[]
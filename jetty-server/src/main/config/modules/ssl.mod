@@ -69,6 +69,9 @@ etc/jetty-ssl-context.xml
 
 ## Whether to include the subdomain property in any Strict-Transport-Security header.
 # jetty.ssl.stsIncludeSubdomains=true
+
+## Whether to compute the JA3/JA4 fingerprint of TLS clients, exposed as request attributes.
+# jetty.ssl.tlsFingerprinting=false
 # end::documentation-connector[]
 
 # tag::documentation-ssl-context[]
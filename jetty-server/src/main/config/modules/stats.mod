@@ -23,3 +23,12 @@ jetty.webapp.addServerClasses+=,-org.eclipse.jetty.servlet.StatisticsServlet
 
 ## If the Graceful shutdown should wait for async requests as well as the currently dispatched ones.
 # jetty.statistics.gracefulShutdownWaitsForRequests=true
+
+## The sliding window in milliseconds of the request time percentiles, or 0 to not gather them.
+# jetty.statistics.requestTimeWindow=0
+
+## The number of leading path segments of the per-path statistics, or 0 to not gather them.
+# jetty.statistics.pathDepth=0
+
+## The max number of paths of the per-path statistics.
+# jetty.statistics.maxPaths=64
@@ -22,6 +22,12 @@ etc/jetty-threadlimit.xml
 ## Enabled by default?
 #jetty.threadlimit.enabled=true
 
-## Thread limit per remote IP
+## Concurrent requests limit per remote IP
 #jetty.threadlimit.threadLimit=10
 
+## Max requests queued per remote IP, -1 for unlimited
+#jetty.threadlimit.maxQueueSize=-1
+
+## Comma separated address patterns of the proxies trusted to add the forwarded header
+#jetty.threadlimit.trustedProxies=10.0.0.0/8,192.168.0.0/16
+
@@ -0,0 +1,31 @@
+[description]
+Enables the RFC 3986 canonicalization of request URI paths.
+Paths are canonicalized when requests are received, dispatched, rewritten and transparently proxied,
+rejecting overlong UTF-8, double encodings, backslashes and decoded dot segments.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-uri-canonicalization.xml
+
+[ini-template]
+# tag::documentation[]
+## How backslashes are handled: REJECT, SEPARATOR or PRESERVE (as %5C).
+# jetty.uriCanonicalizer.backslash=REJECT
+
+## How encoded path separators (%2F) are handled: PRESERVE, DECODE or REJECT.
+# jetty.uriCanonicalizer.separator=PRESERVE
+
+## How encoded non US-ASCII characters are handled: PRESERVE, DECODE or REJECT.
+# jetty.uriCanonicalizer.nonAscii=PRESERVE
+
+## Whether dot segments are removed rather than rejected.
+# jetty.uriCanonicalizer.allowDotSegments=true
+
+## Whether double encodings such as %252e are allowed rather than rejected.
+# jetty.uriCanonicalizer.allowDoubleEncoding=false
+# end::documentation[]
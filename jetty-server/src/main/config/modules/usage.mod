@@ -0,0 +1,26 @@
+[description]
+Accounts the bytes received and sent per API key and enforces
+per API key byte quotas.
+Enable the metrics module to expose the usage as metrics.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-usage.xml
+
+[ini-template]
+## The request header carrying the API key used as accounting key
+#jetty.usage.keyHeader=X-API-Key
+
+## The accounting period in milliseconds, over which quotas are enforced
+#jetty.usage.period=3600000
+
+## The max number of keys tracked, further keys are accounted together
+#jetty.usage.maxKeys=10000
+
+## The bytes quota per period of each key, negative for unlimited
+#jetty.usage.defaultQuota=-1
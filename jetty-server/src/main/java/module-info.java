@@ -22,12 +22,18 @@ module org.eclipse.jetty.server
     requires static java.naming;
     // Only required if using JMX.
     requires static org.eclipse.jetty.jmx;
+    // Only required if using HandlerProfiler.
+    requires static java.management;
+    requires static jdk.management;
 
     exports org.eclipse.jetty.server;
     exports org.eclipse.jetty.server.handler;
+    exports org.eclipse.jetty.server.handler.compression;
     exports org.eclipse.jetty.server.handler.gzip;
     exports org.eclipse.jetty.server.session;
 
+    uses org.eclipse.jetty.server.session.SessionDataCodec;
+
     exports org.eclipse.jetty.server.handler.jmx to
          org.eclipse.jetty.jmx;
 
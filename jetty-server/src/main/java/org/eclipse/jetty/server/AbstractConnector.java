@@ -28,6 +28,8 @@ import java.util.Set;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.Executor;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
 import java.util.concurrent.locks.Condition;
 import java.util.stream.Collectors;
 
@@ -40,6 +42,7 @@ import org.eclipse.jetty.util.ProcessorUtils;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
 import org.eclipse.jetty.util.component.Container;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
 import org.eclipse.jetty.util.component.Dumpable;
@@ -153,6 +156,9 @@ public abstract class AbstractConnector extends ContainerLifeCycle implements Co
     private HttpChannel.Listener _httpChannelListeners = HttpChannel.NOOP_LISTENER;
     private long _idleTimeout = 30000;
     private long _shutdownIdleTimeout = 1000L;
+    private final AtomicReference<DrainPhase> _drainPhase = new AtomicReference<>(DrainPhase.NONE);
+    private long _drainStopAcceptTime = 0;
+    private long _drainWaitTime = 30000L;
     private String _defaultProtocol;
     private ConnectionFactory _defaultConnectionFactory;
     /* The name used to link up virtual host configuration to named connectors */
@@ -313,6 +319,136 @@ public abstract class AbstractConnector extends ContainerLifeCycle implements Co
         return _shutdownIdleTimeout;
     }
 
+    /**
+     * @return the time in milliseconds spent in {@link DrainPhase#STOP_ACCEPT} before advertising close
+     */
+    @ManagedAttribute("The time in ms spent not accepting connections before advertising close during a drain")
+    public long getDrainStopAcceptTime()
+    {
+        return _drainStopAcceptTime;
+    }
+
+    /**
+     * <p>Sets the time spent in {@link DrainPhase#STOP_ACCEPT} during a {@link #drain()},
+     * for example to allow load balancers to notice that this connector does not
+     * accept new connections before the existing connections are asked to close.</p>
+     *
+     * @param drainStopAcceptTime the time in milliseconds
+     */
+    public void setDrainStopAcceptTime(long drainStopAcceptTime)
+    {
+        _drainStopAcceptTime = drainStopAcceptTime;
+    }
+
+    /**
+     * @return the max time in milliseconds spent in {@link DrainPhase#WAIT} before connections are closed
+     */
+    @ManagedAttribute("The max time in ms to wait for connections to close during a drain")
+    public long getDrainWaitTime()
+    {
+        return _drainWaitTime;
+    }
+
+    /**
+     * @param drainWaitTime the max time in milliseconds to wait, during a {@link #drain()},
+     * for connections to close before they are forcibly closed
+     */
+    public void setDrainWaitTime(long drainWaitTime)
+    {
+        _drainWaitTime = drainWaitTime;
+    }
+
+    @ManagedAttribute("The current drain phase")
+    public DrainPhase getDrainPhase()
+    {
+        return _drainPhase.get();
+    }
+
+    @ManagedAttribute("The number of connected endpoints")
+    public int getConnectedEndPointCount()
+    {
+        return _endpoints.size();
+    }
+
+    /**
+     * <p>Drains this connector in phases, each observable via {@link #getDrainPhase()}:</p>
+     * <ol>
+     * <li>{@link DrainPhase#STOP_ACCEPT}: new connections are not accepted anymore,
+     * for {@link #getDrainStopAcceptTime()} milliseconds;</li>
+     * <li>{@link DrainPhase#ADVERTISE_CLOSE}: this connector and the {@link Graceful}
+     * components it contains are shut down, so that, for example, HTTP/2 sessions
+     * send a {@code GOAWAY} frame and HTTP/1.1 responses are sent with
+     * {@code Connection: close};</li>
+     * <li>{@link DrainPhase#WAIT}: connections are given at most
+     * {@link #getDrainWaitTime()} milliseconds to close;</li>
+     * <li>{@link DrainPhase#HARD_CLOSE}: the remaining connections are closed.</li>
+     * </ol>
+     * <p>Unlike {@link #shutdown()}, this method does not require the server to be stopped,
+     * and the connector remains started, but unable to accept connections, once drained.</p>
+     *
+     * @return a future completed when the drain is complete
+     */
+    @ManagedOperation(value = "Drains the connections of this connector", impact = "ACTION")
+    public CompletableFuture<Void> drain()
+    {
+        CompletableFuture<Void> result = new CompletableFuture<>();
+        if (!_drainPhase.compareAndSet(DrainPhase.NONE, DrainPhase.STOP_ACCEPT))
+        {
+            result.completeExceptionally(new IllegalStateException("Drain already in progress: " + getDrainPhase()));
+            return result;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Drain stop accept {}", this);
+        setAccepting(false);
+        if (_drainStopAcceptTime > 0)
+            getScheduler().schedule(() -> advertiseClose(result), _drainStopAcceptTime, TimeUnit.MILLISECONDS);
+        else
+            advertiseClose(result);
+        return result;
+    }
+
+    private void advertiseClose(CompletableFuture<Void> result)
+    {
+        _drainPhase.set(DrainPhase.ADVERTISE_CLOSE);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Drain advertise close {}", this);
+
+        List<CompletableFuture<Void>> futures = new ArrayList<>();
+        futures.add(shutdown());
+        for (Graceful graceful : getContainedBeans(Graceful.class))
+        {
+            futures.add(graceful.shutdown());
+        }
+
+        _drainPhase.set(DrainPhase.WAIT);
+        Scheduler.Task timeout = getScheduler().schedule(() -> hardClose(result), _drainWaitTime, TimeUnit.MILLISECONDS);
+        CompletableFuture.allOf(futures.toArray(new CompletableFuture[0])).whenComplete((r, x) ->
+        {
+            if (_drainPhase.compareAndSet(DrainPhase.WAIT, DrainPhase.DRAINED))
+            {
+                timeout.cancel();
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Drained {}", this, x);
+                result.complete(null);
+            }
+        });
+    }
+
+    private void hardClose(CompletableFuture<Void> result)
+    {
+        if (!_drainPhase.compareAndSet(DrainPhase.WAIT, DrainPhase.HARD_CLOSE))
+            return;
+        if (LOG.isDebugEnabled())
+            LOG.debug("Drain hard close {} endpoints {}", _endpoints.size(), this);
+        for (EndPoint endPoint : _endpoints)
+        {
+            endPoint.close();
+        }
+        _drainPhase.set(DrainPhase.DRAINED);
+        result.complete(null);
+    }
+
     /**
      * @return Returns the number of acceptor threads.
      */
@@ -427,6 +563,7 @@ public abstract class AbstractConnector extends ContainerLifeCycle implements Co
             removeBean(a);
 
         _shutdown = null;
+        _drainPhase.set(DrainPhase.NONE);
 
         LOG.info("Stopped {}", this);
     }
@@ -831,4 +968,35 @@ public abstract class AbstractConnector extends ContainerLifeCycle implements Co
             hashCode(),
             getDefaultProtocol(), getProtocols().stream().collect(Collectors.joining(", ", "(", ")")));
     }
+
+    /**
+     * The phases of a {@link #drain()}.
+     */
+    public enum DrainPhase
+    {
+        /**
+         * No drain in progress.
+         */
+        NONE,
+        /**
+         * New connections are not accepted.
+         */
+        STOP_ACCEPT,
+        /**
+         * Existing connections are asked to close.
+         */
+        ADVERTISE_CLOSE,
+        /**
+         * Waiting for connections to close.
+         */
+        WAIT,
+        /**
+         * Closing the remaining connections.
+         */
+        HARD_CLOSE,
+        /**
+         * All connections are closed.
+         */
+        DRAINED
+    }
 }
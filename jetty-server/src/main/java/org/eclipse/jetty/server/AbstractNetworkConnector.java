@@ -20,6 +20,8 @@ import java.util.concurrent.Executor;
 import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
 import org.eclipse.jetty.util.thread.Scheduler;
 
 /**
@@ -28,7 +30,7 @@ import org.eclipse.jetty.util.thread.Scheduler;
  * Extends the {@link AbstractConnector} support for the {@link NetworkConnector} interface.
  */
 @ManagedObject("AbstractNetworkConnector")
-public abstract class AbstractNetworkConnector extends AbstractConnector implements NetworkConnector
+public abstract class AbstractNetworkConnector extends AbstractConnector implements NetworkConnector, Validatable
 {
     private volatile String _host;
     private volatile int _port = 0;
@@ -68,6 +70,30 @@ public abstract class AbstractNetworkConnector extends AbstractConnector impleme
         return -1;
     }
 
+    /**
+     * <p>Binds then releases the port of this connector, unless it is already open.</p>
+     *
+     * @param report the report to add the problems to
+     */
+    @Override
+    public void validate(ValidationReport report)
+    {
+        if (isOpen())
+            return;
+        try
+        {
+            open();
+        }
+        catch (Throwable x)
+        {
+            report.addProblem(this, x);
+        }
+        finally
+        {
+            close();
+        }
+    }
+
     @Override
     protected void doStart() throws Exception
     {
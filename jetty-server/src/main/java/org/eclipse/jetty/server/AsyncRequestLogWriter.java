@@ -14,21 +14,39 @@
 package org.eclipse.jetty.server;
 
 import java.io.IOException;
+import java.io.InterruptedIOException;
+import java.util.ArrayList;
+import java.util.List;
 import java.util.concurrent.BlockingQueue;
 import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
 
 import org.eclipse.jetty.util.BlockingArrayQueue;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 /**
- * An asynchronously writing RequestLogWriter
+ * <p>An asynchronously writing RequestLogWriter.</p>
+ * <p>Request log entries are queued in a bounded queue and written to the log file
+ * in batches by a dedicated thread, so that a slow disk does not block the threads
+ * serving the requests.
+ * When the queue is full, the {@link OverflowPolicy} decides whether the entry is
+ * dropped or the serving thread waits; dropped entries are counted.
+ * When this writer is stopped, the entries still in the queue are written
+ * before the log file is closed.</p>
  */
+@ManagedObject("Request Log writer which writes asynchronously to file")
 public class AsyncRequestLogWriter extends RequestLogWriter
 {
     private static final Logger LOG = LoggerFactory.getLogger(AsyncRequestLogWriter.class);
     private final BlockingQueue<String> _queue;
+    private final LongAdder _dropped = new LongAdder();
     private transient AsyncRequestLogWriter.WriterThread _thread;
+    private OverflowPolicy _overflowPolicy = OverflowPolicy.DROP_NEWEST;
+    private int _batchSize = 64;
     private boolean _warnedFull;
 
     public AsyncRequestLogWriter()
@@ -41,6 +59,11 @@ public class AsyncRequestLogWriter extends RequestLogWriter
         this(filename, null);
     }
 
+    public AsyncRequestLogWriter(@Name("filename") String filename, @Name("capacity") int capacity)
+    {
+        this(filename, new BlockingArrayQueue<>(capacity));
+    }
+
     public AsyncRequestLogWriter(String filename, BlockingQueue<String> queue)
     {
         super(filename);
@@ -49,6 +72,58 @@ public class AsyncRequestLogWriter extends RequestLogWriter
         _queue = queue;
     }
 
+    /**
+     * @return the policy applied when the queue is full
+     */
+    @ManagedAttribute("The policy applied when the queue is full")
+    public OverflowPolicy getOverflowPolicy()
+    {
+        return _overflowPolicy;
+    }
+
+    /**
+     * @param overflowPolicy the policy applied when the queue is full
+     */
+    public void setOverflowPolicy(OverflowPolicy overflowPolicy)
+    {
+        _overflowPolicy = overflowPolicy == null ? OverflowPolicy.DROP_NEWEST : overflowPolicy;
+    }
+
+    /**
+     * @return the max number of entries written before the log file is flushed
+     */
+    @ManagedAttribute("The max number of entries written before the log file is flushed")
+    public int getBatchSize()
+    {
+        return _batchSize;
+    }
+
+    /**
+     * @param batchSize the max number of entries written before the log file is flushed
+     */
+    public void setBatchSize(int batchSize)
+    {
+        _batchSize = Math.max(1, batchSize);
+    }
+
+    @ManagedAttribute("The number of entries in the queue")
+    public int getQueueSize()
+    {
+        return _queue.size();
+    }
+
+    @ManagedAttribute("The number of entries that can be added to the queue")
+    public int getQueueRemainingCapacity()
+    {
+        return _queue.remainingCapacity();
+    }
+
+    @ManagedAttribute("The number of entries dropped because the queue was full")
+    public long getDroppedEntries()
+    {
+        return _dropped.longValue();
+    }
+
     private class WriterThread extends Thread
     {
         WriterThread()
@@ -59,19 +134,17 @@ public class AsyncRequestLogWriter extends RequestLogWriter
         @Override
         public void run()
         {
+            List<String> batch = new ArrayList<>(getBatchSize());
             while (isRunning())
             {
                 try
                 {
                     String log = _queue.poll(10, TimeUnit.SECONDS);
                     if (log != null)
-                        AsyncRequestLogWriter.super.write(log);
-
-                    while (!_queue.isEmpty())
                     {
-                        log = _queue.poll();
-                        if (log != null)
-                            AsyncRequestLogWriter.super.write(log);
+                        batch.add(log);
+                        writeBatch(batch);
+                        drain(batch);
                     }
                 }
                 catch (InterruptedException e)
@@ -82,14 +155,39 @@ public class AsyncRequestLogWriter extends RequestLogWriter
                 {
                     LOG.warn("Failed to write log", t);
                 }
+                finally
+                {
+                    batch.clear();
+                }
             }
         }
     }
 
+    private void drain(List<String> batch) throws IOException
+    {
+        while (true)
+        {
+            batch.clear();
+            _queue.drainTo(batch, getBatchSize());
+            if (batch.isEmpty())
+                return;
+            writeBatch(batch);
+        }
+    }
+
+    private void writeBatch(List<String> batch) throws IOException
+    {
+        if (batch.size() == 1)
+            super.write(batch.get(0));
+        else
+            super.write(batch);
+    }
+
     @Override
     protected void doStart() throws Exception
     {
         super.doStart();
+        _warnedFull = false;
         _thread = new AsyncRequestLogWriter.WriterThread();
         _thread.start();
     }
@@ -99,6 +197,15 @@ public class AsyncRequestLogWriter extends RequestLogWriter
     {
         _thread.interrupt();
         _thread.join();
+        try
+        {
+            // Write the entries still queued before closing the file.
+            drain(new ArrayList<>(getBatchSize()));
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Failed to write log", x);
+        }
         super.doStop();
         _thread = null;
     }
@@ -106,11 +213,68 @@ public class AsyncRequestLogWriter extends RequestLogWriter
     @Override
     public void write(String log) throws IOException
     {
-        if (!_queue.offer(log))
+        switch (_overflowPolicy)
+        {
+            case BLOCK:
+            {
+                try
+                {
+                    _queue.put(log);
+                }
+                catch (InterruptedException x)
+                {
+                    throw new InterruptedIOException();
+                }
+                break;
+            }
+            case DROP_OLDEST:
+            {
+                while (!_queue.offer(log))
+                {
+                    if (_queue.poll() != null)
+                        onDropped();
+                }
+                break;
+            }
+            case DROP_NEWEST:
+            {
+                if (!_queue.offer(log))
+                    onDropped();
+                break;
+            }
+            default:
+            {
+                throw new IllegalStateException(_overflowPolicy.toString());
+            }
+        }
+    }
+
+    private void onDropped()
+    {
+        _dropped.increment();
+        if (!_warnedFull)
         {
-            if (_warnedFull)
-                LOG.warn("Log Queue overflow");
             _warnedFull = true;
+            LOG.warn("Log Queue overflow, dropping entries");
         }
     }
+
+    /**
+     * <p>The policy applied when a request log entry is written and the queue is full.</p>
+     */
+    public enum OverflowPolicy
+    {
+        /**
+         * The writing thread waits until there is space in the queue.
+         */
+        BLOCK,
+        /**
+         * The oldest entry in the queue is dropped to make space for the new entry.
+         */
+        DROP_OLDEST,
+        /**
+         * The new entry is dropped.
+         */
+        DROP_NEWEST
+    }
 }
@@ -0,0 +1,141 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import org.eclipse.jetty.io.BandwidthLimitEndPoint;
+import org.eclipse.jetty.io.BandwidthLimiter;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.Name;
+
+/**
+ * <p>A {@link ConnectionFactory} that limits the read and write throughput of each connection.</p>
+ * <p>This factory must be the first in the list of the connector's connection factories,
+ * and wraps the network {@link EndPoint} with a {@link BandwidthLimitEndPoint}, so that the
+ * limits apply to all the bytes read and written by the connection, whatever the protocol
+ * (including TLS) spoken by the connection factories that follow, for example:</p>
+ * <pre>{@code
+ * BandwidthLimitConnectionFactory bandwidth = new BandwidthLimitConnectionFactory(http.getProtocol());
+ * bandwidth.setWriteRate(64 * 1024);
+ * ServerConnector connector = new ServerConnector(server, bandwidth, http);
+ * }</pre>
+ * <p>This is useful, for example, to simulate slow networks in tests, or to
+ * prevent a single connection from using all the available bandwidth.</p>
+ *
+ * @see org.eclipse.jetty.server.handler.BandwidthLimitHandler
+ */
+@ManagedObject("Bandwidth limiting connection factory")
+public class BandwidthLimitConnectionFactory extends AbstractConnectionFactory
+{
+    private final String _nextProtocol;
+    private long _readRate = -1;
+    private long _writeRate = -1;
+    private long _burst = 16 * 1024;
+
+    public BandwidthLimitConnectionFactory()
+    {
+        this(null);
+    }
+
+    /**
+     * @param nextProtocol the protocol of the connection factory that follows this factory
+     */
+    public BandwidthLimitConnectionFactory(@Name("next") String nextProtocol)
+    {
+        super("bandwidth-limit");
+        _nextProtocol = nextProtocol;
+    }
+
+    @ManagedAttribute("The max number of bytes per second read by each connection, or -1 for unlimited")
+    public long getReadRate()
+    {
+        return _readRate;
+    }
+
+    public void setReadRate(long readRate)
+    {
+        _readRate = readRate;
+    }
+
+    @ManagedAttribute("The max number of bytes per second written by each connection, or -1 for unlimited")
+    public long getWriteRate()
+    {
+        return _writeRate;
+    }
+
+    public void setWriteRate(long writeRate)
+    {
+        _writeRate = writeRate;
+    }
+
+    @ManagedAttribute("The max number of bytes read or written at once")
+    public long getBurst()
+    {
+        return _burst;
+    }
+
+    public void setBurst(long burst)
+    {
+        if (burst <= 0)
+            throw new IllegalArgumentException("Invalid burst " + burst);
+        _burst = burst;
+    }
+
+    @Override
+    public Connection newConnection(Connector connector, EndPoint endPoint)
+    {
+        String nextProtocol = _nextProtocol;
+        if (nextProtocol == null)
+            nextProtocol = findNextProtocol(connector);
+        ConnectionFactory connectionFactory = nextProtocol == null ? null : connector.getConnectionFactory(nextProtocol);
+        if (connectionFactory == null)
+            throw new IllegalStateException("No next protocol after '" + getProtocol() + "' in connector's protocol list " + connector.getProtocols() + " for " + endPoint);
+
+        BandwidthLimitEndPoint limitedEndPoint = new BandwidthLimitEndPoint(endPoint, connector.getScheduler(), newReadLimiter(endPoint), newWriteLimiter(endPoint));
+        return connectionFactory.newConnection(connector, limitedEndPoint);
+    }
+
+    /**
+     * <p>Creates the {@link BandwidthLimiter} for the reads of a new connection.</p>
+     * <p>Applications may override this method to share limiters across connections,
+     * for example to limit the aggregate throughput of the connections from the same address.</p>
+     *
+     * @param endPoint the network {@link EndPoint} of the new connection
+     * @return a {@link BandwidthLimiter}, or null to not limit reads
+     */
+    protected BandwidthLimiter newReadLimiter(EndPoint endPoint)
+    {
+        return _readRate > 0 ? new BandwidthLimiter(_readRate, _burst) : null;
+    }
+
+    /**
+     * <p>Creates the {@link BandwidthLimiter} for the writes of a new connection.</p>
+     *
+     * @param endPoint the network {@link EndPoint} of the new connection
+     * @return a {@link BandwidthLimiter}, or null to not limit writes
+     * @see #newReadLimiter(EndPoint)
+     */
+    protected BandwidthLimiter newWriteLimiter(EndPoint endPoint)
+    {
+        return _writeRate > 0 ? new BandwidthLimiter(_writeRate, _burst) : null;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[read=%d/s,write=%d/s,next=%s]", getClass().getSimpleName(), hashCode(), _readRate, _writeRate, _nextProtocol);
+    }
+}
@@ -0,0 +1,150 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.security.cert.CertificateEncodingException;
+import java.security.cert.X509Certificate;
+import java.util.ArrayList;
+import java.util.List;
+import javax.net.ssl.SSLSession;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.StructuredFields;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Customizer for a TLS-terminating proxy, that serializes the TLS client certificate
+ * chain into the {@code Client-Cert} and {@code Client-Cert-Chain} request headers
+ * specified by <a href="https://www.rfc-editor.org/rfc/rfc9440">RFC 9440</a>, so that
+ * they are forwarded to the backend servers, for example by a {@code ProxyServlet}.</p>
+ * <p>Any {@code Client-Cert} and {@code Client-Cert-Chain} headers sent by the client
+ * are always removed, so that clients cannot forge them.</p>
+ * <p>The backend servers may use {@link ForwardedClientCertCustomizer} to reconstitute
+ * the certificate chain from the headers.</p>
+ */
+public class ClientCertHeadersCustomizer implements HttpConfiguration.Customizer
+{
+    /**
+     * The RFC 9440 header carrying the client certificate.
+     */
+    public static final String CLIENT_CERT = "Client-Cert";
+    /**
+     * The RFC 9440 header carrying the client certificate chain, excluding the client certificate.
+     */
+    public static final String CLIENT_CERT_CHAIN = "Client-Cert-Chain";
+
+    private static final Logger LOG = LoggerFactory.getLogger(ClientCertHeadersCustomizer.class);
+    private static final String HEADERS_ATTRIBUTE = ClientCertHeadersCustomizer.class.getName() + ".headers";
+
+    private boolean includeChain = true;
+
+    /**
+     * @return whether the {@code Client-Cert-Chain} header is added
+     */
+    public boolean isIncludeChain()
+    {
+        return includeChain;
+    }
+
+    /**
+     * @param includeChain whether the {@code Client-Cert-Chain} header is added
+     */
+    public void setIncludeChain(boolean includeChain)
+    {
+        this.includeChain = includeChain;
+    }
+
+    @Override
+    public void customize(Connector connector, HttpConfiguration channelConfig, Request request)
+    {
+        HttpFields original = request.getHttpFields();
+        String[] headers = getHeaders(request);
+        if (headers == null && !original.contains(CLIENT_CERT) && !original.contains(CLIENT_CERT_CHAIN))
+            return;
+
+        HttpFields.Mutable httpFields = HttpFields.build(original);
+        httpFields.remove(CLIENT_CERT);
+        httpFields.remove(CLIENT_CERT_CHAIN);
+        if (headers != null)
+        {
+            httpFields.add(CLIENT_CERT, headers[0]);
+            if (includeChain && headers[1] != null)
+                httpFields.add(CLIENT_CERT_CHAIN, headers[1]);
+        }
+        request.setHttpFields(httpFields);
+    }
+
+    private String[] getHeaders(Request request)
+    {
+        EndPoint endPoint = request.getHttpChannel().getEndPoint();
+        if (!(endPoint instanceof SslConnection.DecryptedEndPoint))
+            return null;
+        SSLSession sslSession = ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getSSLEngine().getSession();
+
+        // The headers are computed once per TLS session.
+        String[] headers = (String[])sslSession.getValue(HEADERS_ATTRIBUTE);
+        if (headers != null)
+            return headers.length == 0 ? null : headers;
+
+        headers = new String[0];
+        X509Certificate[] certs = getCertChain(request.getHttpChannel().getConnector(), sslSession);
+        if (certs != null && certs.length > 0)
+        {
+            try
+            {
+                String clientCert = StructuredFields.serialize(new StructuredFields.Item(certs[0].getEncoded()));
+                String clientCertChain = null;
+                if (certs.length > 1)
+                {
+                    List<StructuredFields.Item> chain = new ArrayList<>(certs.length - 1);
+                    for (int i = 1; i < certs.length; ++i)
+                    {
+                        chain.add(new StructuredFields.Item(certs[i].getEncoded()));
+                    }
+                    clientCertChain = StructuredFields.serialize(chain);
+                }
+                headers = new String[]{clientCert, clientCertChain};
+            }
+            catch (CertificateEncodingException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not encode client certificate chain", x);
+            }
+        }
+        sslSession.putValue(HEADERS_ATTRIBUTE, headers);
+        return headers.length == 0 ? null : headers;
+    }
+
+    private X509Certificate[] getCertChain(Connector connector, SSLSession sslSession)
+    {
+        SslConnectionFactory sslConnectionFactory = connector.getConnectionFactory(SslConnectionFactory.class);
+        if (sslConnectionFactory != null)
+        {
+            SslContextFactory sslContextFactory = sslConnectionFactory.getSslContextFactory();
+            if (sslContextFactory != null)
+                return sslContextFactory.getX509CertChain(sslSession);
+        }
+        return SslContextFactory.getCertChain(sslSession);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[includeChain=%b]", getClass().getSimpleName(), hashCode(), includeChain);
+    }
+}
@@ -0,0 +1,107 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.util.Map;
+import java.util.TreeMap;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http.ComplianceViolation;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ComplianceViolation.Listener} that counts the violations detected
+ * while parsing requests, separating allowed violations from rejected requests.</p>
+ * <p>Add an instance as a bean of a {@link Connector} to count the violations
+ * of the requests received by that connector:</p>
+ * <pre>
+ * ServerConnector connector = new ServerConnector(server);
+ * connector.addBean(new ComplianceViolationStatistics());
+ * </pre>
+ * <p>Combined with the {@link org.eclipse.jetty.http.HttpCompliance#PARANOID} mode, the
+ * rejected counts measure attempts of request smuggling; rejected violations are also
+ * logged at DEBUG level.</p>
+ */
+@ManagedObject("Compliance violation statistics")
+public class ComplianceViolationStatistics implements ComplianceViolation.Listener
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ComplianceViolationStatistics.class);
+
+    private final ConcurrentMap<String, LongAdder> _allowed = new ConcurrentHashMap<>();
+    private final ConcurrentMap<String, LongAdder> _rejected = new ConcurrentHashMap<>();
+
+    @Override
+    public void onComplianceViolation(ComplianceViolation.Event event)
+    {
+        if (!event.isAllowed() && LOG.isDebugEnabled())
+            LOG.debug("Rejected {}", event);
+        ConcurrentMap<String, LongAdder> counters = event.isAllowed() ? _allowed : _rejected;
+        counters.computeIfAbsent(event.getViolation().getName(), k -> new LongAdder()).increment();
+    }
+
+    @ManagedAttribute("The total number of allowed violations")
+    public long getAllowedViolations()
+    {
+        return sum(_allowed);
+    }
+
+    @ManagedAttribute("The total number of violations that caused a request to be rejected")
+    public long getRejectedViolations()
+    {
+        return sum(_rejected);
+    }
+
+    @ManagedAttribute("The number of allowed violations by violation name")
+    public Map<String, Long> getAllowedViolationsByName()
+    {
+        return snapshot(_allowed);
+    }
+
+    @ManagedAttribute("The number of rejected requests by violation name")
+    public Map<String, Long> getRejectedViolationsByName()
+    {
+        return snapshot(_rejected);
+    }
+
+    @ManagedOperation("Resets the statistics")
+    public void reset()
+    {
+        _allowed.clear();
+        _rejected.clear();
+    }
+
+    private static long sum(Map<String, LongAdder> counters)
+    {
+        return counters.values().stream().mapToLong(LongAdder::sum).sum();
+    }
+
+    private static Map<String, Long> snapshot(Map<String, LongAdder> counters)
+    {
+        Map<String, Long> result = new TreeMap<>();
+        counters.forEach((name, counter) -> result.put(name, counter.sum()));
+        return result;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[allowed=%d,rejected=%d]", getClass().getSimpleName(), hashCode(), getAllowedViolations(), getRejectedViolations());
+    }
+}
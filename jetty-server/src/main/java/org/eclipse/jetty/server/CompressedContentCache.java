@@ -0,0 +1,720 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.channels.Channels;
+import java.nio.channels.ReadableByteChannel;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.Iterator;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.Executor;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http.CompressedContentFormat;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpContent;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.http.PrecompressedHttpContent;
+import org.eclipse.jetty.util.AsciiLowerCaseSet;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.resource.Resource;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A cache of compressed variants of static content, generated on the fly.</p>
+ * <p>When a resource has been requested at least {@link #setMinHits(int) min hits}
+ * times, its content is compressed in the background with the configured
+ * {@link #setEncodings(String...) encodings}, and the compressed variants are kept
+ * in memory within a {@link #setMaxCacheSize(long) size} and
+ * {@link #setMaxCachedFiles(int) count} budget, evicting the least recently used
+ * entries first.
+ * Compressed variants are then offered, via {@link HttpContent#getPrecompressedContents()},
+ * by the {@link HttpContent.ContentFactory} returned by {@link #wrap(HttpContent.ContentFactory)},
+ * alongside any precompressed sibling files, that take precedence.</p>
+ * <p>Entries are invalidated when the resource last modified time or length change.</p>
+ */
+@ManagedObject("Cache of compressed variants of static content")
+public class CompressedContentCache
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CompressedContentCache.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final Map<String, Entry> _cache = new LinkedHashMap<>(16, 0.75F, true);
+    private final Map<String, AtomicInteger> _hits = new ConcurrentHashMap<>();
+    private final Set<String> _pending = ConcurrentHashMap.newKeySet();
+    private final List<ContentCodec> _codecs = new ArrayList<>();
+    private final IncludeExclude<String> _mimeTypes = new IncludeExclude<>(AsciiLowerCaseSet.class);
+    private final LongAdder _compressions = new LongAdder();
+    private Executor _executor;
+    private int _minHits = 2;
+    private long _minContentLength = 256;
+    private long _maxContentLength = 1024 * 1024;
+    private long _maxCacheSize = 16 * 1024 * 1024;
+    private int _maxCachedFiles = 1024;
+    private long _cacheSize;
+
+    public CompressedContentCache()
+    {
+        for (ContentCodec codec : ContentCodec.getContentCodecs())
+        {
+            if (codec.isEncodingSupported())
+                _codecs.add(codec);
+        }
+        for (String type : MimeTypes.getKnownMimeTypes())
+        {
+            if (type.startsWith("text/") ||
+                type.endsWith("/javascript") ||
+                type.endsWith("json") ||
+                type.endsWith("xml") ||
+                "application/wasm".equals(type))
+                _mimeTypes.include(type);
+        }
+        _mimeTypes.exclude("text/event-stream");
+    }
+
+    /**
+     * @return the executor used to compress the content, or {@code null} to compress in the caller thread
+     */
+    public Executor getExecutor()
+    {
+        return _executor;
+    }
+
+    /**
+     * @param executor the executor used to compress the content, or {@code null} to compress in the caller thread
+     */
+    public void setExecutor(Executor executor)
+    {
+        _executor = executor;
+    }
+
+    /**
+     * @return the content codings of the generated variants
+     */
+    @ManagedAttribute("The content codings of the generated variants")
+    public String[] getEncodings()
+    {
+        return _codecs.stream().map(ContentCodec::getEncoding).toArray(String[]::new);
+    }
+
+    /**
+     * @param encodings the content codings of the generated variants
+     * @throws IllegalArgumentException if a content coding is not available or does not support encoding
+     */
+    public void setEncodings(String... encodings)
+    {
+        List<ContentCodec> codecs = new ArrayList<>();
+        for (String encoding : encodings)
+        {
+            ContentCodec codec = ContentCodec.getContentCodec(encoding.trim());
+            if (codec == null || !codec.isEncodingSupported())
+                throw new IllegalArgumentException("Unsupported content coding " + encoding);
+            codecs.add(codec);
+        }
+        _codecs.clear();
+        _codecs.addAll(codecs);
+    }
+
+    /**
+     * @return the formats of the generated variants, to be configured as precompressed formats
+     */
+    public CompressedContentFormat[] getCompressedContentFormats()
+    {
+        return _codecs.stream().map(c -> toCompressedContentFormat(c.getEncoding())).toArray(CompressedContentFormat[]::new);
+    }
+
+    /**
+     * @param csv a comma separated list of content codings
+     * @see #setEncodings(String...)
+     */
+    public void setEncodingList(String csv)
+    {
+        setEncodings(StringUtil.csvSplit(csv));
+    }
+
+    @ManagedAttribute("The included mime types")
+    public String[] getIncludedMimeTypes()
+    {
+        return _mimeTypes.getIncluded().toArray(new String[0]);
+    }
+
+    /**
+     * @param types the mime types to compress, replacing the default textual mime types
+     */
+    public void setIncludedMimeTypes(String... types)
+    {
+        _mimeTypes.getIncluded().clear();
+        _mimeTypes.include(types);
+    }
+
+    public void setIncludedMimeTypesList(String csv)
+    {
+        setIncludedMimeTypes(StringUtil.csvSplit(csv));
+    }
+
+    @ManagedAttribute("The number of requests of a resource before it is compressed")
+    public int getMinHits()
+    {
+        return _minHits;
+    }
+
+    /**
+     * @param minHits the number of requests of a resource before its content is compressed
+     */
+    public void setMinHits(int minHits)
+    {
+        _minHits = minHits;
+    }
+
+    @ManagedAttribute("The min content length to compress")
+    public long getMinContentLength()
+    {
+        return _minContentLength;
+    }
+
+    public void setMinContentLength(long minContentLength)
+    {
+        _minContentLength = minContentLength;
+    }
+
+    @ManagedAttribute("The max content length to compress")
+    public long getMaxContentLength()
+    {
+        return _maxContentLength;
+    }
+
+    public void setMaxContentLength(long maxContentLength)
+    {
+        _maxContentLength = maxContentLength;
+    }
+
+    @ManagedAttribute("The max total size in bytes of the compressed variants")
+    public long getMaxCacheSize()
+    {
+        return _maxCacheSize;
+    }
+
+    public void setMaxCacheSize(long maxCacheSize)
+    {
+        _maxCacheSize = maxCacheSize;
+    }
+
+    @ManagedAttribute("The max number of resources with cached compressed variants")
+    public int getMaxCachedFiles()
+    {
+        return _maxCachedFiles;
+    }
+
+    public void setMaxCachedFiles(int maxCachedFiles)
+    {
+        _maxCachedFiles = maxCachedFiles;
+    }
+
+    @ManagedAttribute(value = "The total size in bytes of the compressed variants", readonly = true)
+    public long getCacheSize()
+    {
+        try (AutoLock ignored = _lock.lock())
+        {
+            return _cacheSize;
+        }
+    }
+
+    @ManagedAttribute(value = "The number of resources with cached compressed variants", readonly = true)
+    public int getCachedFiles()
+    {
+        try (AutoLock ignored = _lock.lock())
+        {
+            return _cache.size();
+        }
+    }
+
+    @ManagedAttribute(value = "The number of resources compressed", readonly = true)
+    public long getCompressions()
+    {
+        return _compressions.sum();
+    }
+
+    @ManagedOperation(value = "Clears the cache", impact = "ACTION")
+    public void clear()
+    {
+        try (AutoLock ignored = _lock.lock())
+        {
+            _cache.clear();
+            _cacheSize = 0;
+        }
+        _hits.clear();
+    }
+
+    /**
+     * @param factory the factory of the content to compress
+     * @return a factory whose content offers the cached compressed variants
+     */
+    public HttpContent.ContentFactory wrap(HttpContent.ContentFactory factory)
+    {
+        return (pathInContext, maxBufferSize) -> getContent(factory.getContent(pathInContext, maxBufferSize), pathInContext);
+    }
+
+    private HttpContent getContent(HttpContent content, String pathInContext)
+    {
+        if (content == null || !isCompressible(content))
+            return content;
+
+        Resource resource = content.getResource();
+        Entry entry;
+        try (AutoLock ignored = _lock.lock())
+        {
+            entry = _cache.get(pathInContext);
+            if (entry != null && !entry.isValid(resource))
+            {
+                _cache.remove(pathInContext);
+                _cacheSize -= entry._size;
+                entry = null;
+            }
+        }
+
+        if (entry != null)
+            return entry._variants.isEmpty() ? content : new VariantsHttpContent(content, entry._variants);
+
+        if (hit(pathInContext) && _pending.add(pathInContext))
+        {
+            Executor executor = getExecutor();
+            Runnable task = () -> compress(pathInContext, resource, content.getContentLengthValue());
+            if (executor == null)
+                task.run();
+            else
+                executor.execute(task);
+        }
+        return content;
+    }
+
+    private boolean isCompressible(HttpContent content)
+    {
+        if (_codecs.isEmpty() || content.getContentEncoding() != null)
+            return false;
+        Resource resource = content.getResource();
+        if (resource == null || resource.isDirectory())
+            return false;
+        long length = content.getContentLengthValue();
+        if (length < getMinContentLength() || length > getMaxContentLength())
+            return false;
+        String mimeType = MimeTypes.getContentTypeWithoutCharset(content.getContentTypeValue());
+        return mimeType != null && _mimeTypes.test(StringUtil.asciiToLowerCase(mimeType));
+    }
+
+    private boolean hit(String pathInContext)
+    {
+        // Keep the hit counters in check even when
+        // many resources are requested only once.
+        if (_hits.size() > 4 * Math.max(1, getMaxCachedFiles()))
+            _hits.clear();
+        return _hits.computeIfAbsent(pathInContext, p -> new AtomicInteger()).incrementAndGet() >= getMinHits();
+    }
+
+    private void compress(String pathInContext, Resource resource, long length)
+    {
+        try
+        {
+            long lastModified = resource.lastModified();
+            byte[] bytes;
+            try (InputStream input = resource.getInputStream())
+            {
+                bytes = IO.readBytes(input);
+            }
+
+            Map<CompressedContentFormat, byte[]> variants = new HashMap<>();
+            int size = 0;
+            for (ContentCodec codec : _codecs)
+            {
+                ByteArrayOutputStream output = new ByteArrayOutputStream(bytes.length / 2);
+                try (OutputStream encoder = codec.newEncoder(output, -1))
+                {
+                    encoder.write(bytes);
+                }
+                // Only keep the variants that are worth serving.
+                if (output.size() < bytes.length)
+                {
+                    variants.put(toCompressedContentFormat(codec.getEncoding()), output.toByteArray());
+                    size += output.size();
+                }
+            }
+            _compressions.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("compressed {} {}->{} bytes in {}", pathInContext, bytes.length, size, variants.keySet());
+
+            // Entries without variants are kept so that
+            // incompressible content is not compressed again.
+            Entry entry = new Entry(lastModified, length, variants, size);
+            try (AutoLock ignored = _lock.lock())
+            {
+                Entry old = _cache.put(pathInContext, entry);
+                if (old != null)
+                    _cacheSize -= old._size;
+                _cacheSize += size;
+                evict();
+            }
+            _hits.remove(pathInContext);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not compress {}", pathInContext, x);
+        }
+        finally
+        {
+            _pending.remove(pathInContext);
+        }
+    }
+
+    private void evict()
+    {
+        assert _lock.isHeldByCurrentThread();
+        Iterator<Entry> iterator = _cache.values().iterator();
+        while (iterator.hasNext() && (_cache.size() > getMaxCachedFiles() || _cacheSize > getMaxCacheSize()))
+        {
+            Entry entry = iterator.next();
+            iterator.remove();
+            _cacheSize -= entry._size;
+        }
+    }
+
+    private static CompressedContentFormat toCompressedContentFormat(String encoding)
+    {
+        for (CompressedContentFormat format : new CompressedContentFormat[]{CompressedContentFormat.GZIP, CompressedContentFormat.BR, CompressedContentFormat.ZSTD})
+        {
+            if (format.getEncoding().equals(encoding))
+                return format;
+        }
+        return new CompressedContentFormat(encoding, "." + encoding);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{files=%d,size=%d}", getClass().getSimpleName(), hashCode(), getCachedFiles(), getCacheSize());
+    }
+
+    private static class Entry
+    {
+        private final long _lastModified;
+        private final long _length;
+        private final Map<CompressedContentFormat, byte[]> _variants;
+        private final int _size;
+
+        private Entry(long lastModified, long length, Map<CompressedContentFormat, byte[]> variants, int size)
+        {
+            _lastModified = lastModified;
+            _length = length;
+            _variants = variants;
+            _size = size;
+        }
+
+        private boolean isValid(Resource resource)
+        {
+            return resource.lastModified() == _lastModified && resource.length() == _length;
+        }
+    }
+
+    /**
+     * <p>The content of the original resource, offering also the cached compressed variants.</p>
+     */
+    private static class VariantsHttpContent implements HttpContent
+    {
+        private final HttpContent _content;
+        private final Map<CompressedContentFormat, HttpContent> _precompressedContents;
+
+        private VariantsHttpContent(HttpContent content, Map<CompressedContentFormat, byte[]> variants)
+        {
+            _content = content;
+            _precompressedContents = new HashMap<>();
+            for (Map.Entry<CompressedContentFormat, byte[]> variant : variants.entrySet())
+            {
+                CompressedContentFormat format = variant.getKey();
+                _precompressedContents.put(format, new PrecompressedHttpContent(this, new BytesHttpContent(content, variant.getValue()), format));
+            }
+            // Precompressed sibling files take precedence.
+            Map<CompressedContentFormat, ? extends HttpContent> precompressed = content.getPrecompressedContents();
+            if (precompressed != null)
+                _precompressedContents.putAll(precompressed);
+        }
+
+        @Override
+        public HttpField getContentType()
+        {
+            return _content.getContentType();
+        }
+
+        @Override
+        public String getContentTypeValue()
+        {
+            return _content.getContentTypeValue();
+        }
+
+        @Override
+        public String getCharacterEncoding()
+        {
+            return _content.getCharacterEncoding();
+        }
+
+        @Override
+        public MimeTypes.Type getMimeType()
+        {
+            return _content.getMimeType();
+        }
+
+        @Override
+        public HttpField getContentEncoding()
+        {
+            return _content.getContentEncoding();
+        }
+
+        @Override
+        public String getContentEncodingValue()
+        {
+            return _content.getContentEncodingValue();
+        }
+
+        @Override
+        public HttpField getContentLength()
+        {
+            return _content.getContentLength();
+        }
+
+        @Override
+        public long getContentLengthValue()
+        {
+            return _content.getContentLengthValue();
+        }
+
+        @Override
+        public HttpField getLastModified()
+        {
+            return _content.getLastModified();
+        }
+
+        @Override
+        public String getLastModifiedValue()
+        {
+            return _content.getLastModifiedValue();
+        }
+
+        @Override
+        public HttpField getETag()
+        {
+            return _content.getETag();
+        }
+
+        @Override
+        public String getETagValue()
+        {
+            return _content.getETagValue();
+        }
+
+        @Override
+        public ByteBuffer getIndirectBuffer()
+        {
+            return _content.getIndirectBuffer();
+        }
+
+        @Override
+        public ByteBuffer getDirectBuffer()
+        {
+            return _content.getDirectBuffer();
+        }
+
+        @Override
+        public Resource getResource()
+        {
+            return _content.getResource();
+        }
+
+        @Override
+        public InputStream getInputStream() throws IOException
+        {
+            return _content.getInputStream();
+        }
+
+        @Override
+        public ReadableByteChannel getReadableByteChannel() throws IOException
+        {
+            return _content.getReadableByteChannel();
+        }
+
+        @Override
+        public void release()
+        {
+            _content.release();
+        }
+
+        @Override
+        public Map<CompressedContentFormat, HttpContent> getPrecompressedContents()
+        {
+            return _precompressedContents;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s,variants=%s}", getClass().getSimpleName(), hashCode(), _content, _precompressedContents.keySet());
+        }
+    }
+
+    /**
+     * <p>The in-memory bytes of a compressed variant.</p>
+     */
+    private static class BytesHttpContent implements HttpContent
+    {
+        private final HttpContent _content;
+        private final byte[] _bytes;
+
+        private BytesHttpContent(HttpContent content, byte[] bytes)
+        {
+            _content = content;
+            _bytes = bytes;
+        }
+
+        @Override
+        public HttpField getContentType()
+        {
+            return _content.getContentType();
+        }
+
+        @Override
+        public String getContentTypeValue()
+        {
+            return _content.getContentTypeValue();
+        }
+
+        @Override
+        public String getCharacterEncoding()
+        {
+            return _content.getCharacterEncoding();
+        }
+
+        @Override
+        public MimeTypes.Type getMimeType()
+        {
+            return _content.getMimeType();
+        }
+
+        @Override
+        public HttpField getContentEncoding()
+        {
+            return null;
+        }
+
+        @Override
+        public String getContentEncodingValue()
+        {
+            return null;
+        }
+
+        @Override
+        public HttpField getContentLength()
+        {
+            return new HttpField.LongValueHttpField(HttpHeader.CONTENT_LENGTH, _bytes.length);
+        }
+
+        @Override
+        public long getContentLengthValue()
+        {
+            return _bytes.length;
+        }
+
+        @Override
+        public HttpField getLastModified()
+        {
+            return _content.getLastModified();
+        }
+
+        @Override
+        public String getLastModifiedValue()
+        {
+            return _content.getLastModifiedValue();
+        }
+
+        @Override
+        public HttpField getETag()
+        {
+            return _content.getETag();
+        }
+
+        @Override
+        public String getETagValue()
+        {
+            return _content.getETagValue();
+        }
+
+        @Override
+        public ByteBuffer getIndirectBuffer()
+        {
+            return ByteBuffer.wrap(_bytes).asReadOnlyBuffer();
+        }
+
+        @Override
+        public ByteBuffer getDirectBuffer()
+        {
+            return null;
+        }
+
+        @Override
+        public Resource getResource()
+        {
+            return _content.getResource();
+        }
+
+        @Override
+        public InputStream getInputStream()
+        {
+            return new ByteArrayInputStream(_bytes);
+        }
+
+        @Override
+        public ReadableByteChannel getReadableByteChannel()
+        {
+            return Channels.newChannel(getInputStream());
+        }
+
+        @Override
+        public void release()
+        {
+        }
+
+        @Override
+        public Map<CompressedContentFormat, ? extends HttpContent> getPrecompressedContents()
+        {
+            return null;
+        }
+    }
+}
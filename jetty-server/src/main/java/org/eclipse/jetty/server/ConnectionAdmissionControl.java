@@ -0,0 +1,666 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.net.UnknownHostException;
+import java.nio.channels.SelectableChannel;
+import java.nio.channels.SocketChannel;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.Iterator;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.SelectorManager;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.component.Container;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A Listener that applies admission control to accepted connections, based on their remote address.</p>
+ * <p>Where {@link ConnectionLimit} and {@link AcceptRateLimit} limit the total number of connections
+ * and the global accept rate by suspending accepting, this listener rejects individual connections,
+ * by closing them as soon as they are accepted, when:</p>
+ * <ul>
+ *   <li>the remote IP address already has {@link #getMaxConnectionsPerAddress()} connections</li>
+ *   <li>the subnet of the remote IP address already has {@link #getMaxConnectionsPerSubnet()} connections;
+ *   the subnet is determined by {@link #getIPv4SubnetPrefixLength()} or {@link #getIPv6SubnetPrefixLength()}</li>
+ *   <li>the remote IP address is greylisted</li>
+ * </ul>
+ * <p>A remote IP address is greylisted for {@link #getGreylistPeriod()} ms when it opens more than
+ * {@link #getMaxAcceptRatePerAddress()} connections within {@link #getAcceptRatePeriod()} ms,
+ * typical of connection floods, or when it is explicitly {@link #greylist(String) greylisted}.</p>
+ * <p>It can be applied to an entire server or to a specific connector by adding it
+ * via {@link Container#addBean(Object)}, and it can be combined with {@link AcceptRateLimit}
+ * to also throttle the global accept rate.</p>
+ * <p>
+ * <b>Usage:</b>
+ * </p>
+ * <pre>
+ *   Server server = new Server();
+ *   ConnectionAdmissionControl admission = new ConnectionAdmissionControl(server);
+ *   admission.setMaxConnectionsPerAddress(64);
+ *   admission.setMaxAcceptRatePerAddress(50);
+ *   server.addBean(admission);
+ *   server.addBean(new AcceptRateLimit(1000, 1, TimeUnit.SECONDS, server));
+ *   ...
+ *   server.start();
+ * </pre>
+ *
+ * @see ConnectionLimit
+ * @see AcceptRateLimit
+ */
+@ManagedObject
+public class ConnectionAdmissionControl extends AbstractLifeCycle implements Connection.Listener, SelectorManager.AcceptListener, Runnable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ConnectionAdmissionControl.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final Server _server;
+    private final List<AbstractConnector> _connectors = new ArrayList<>();
+    private final Map<SelectableChannel, Admission> _admitted = new HashMap<>();
+    private final Map<InetAddress, Integer> _addressConnections = new HashMap<>();
+    private final Map<InetAddress, Integer> _subnetConnections = new HashMap<>();
+    private final Map<InetAddress, AcceptWindow> _acceptWindows = new HashMap<>();
+    private final Map<InetAddress, Long> _greylist = new HashMap<>();
+    private final LongAdder _rejectedByAddress = new LongAdder();
+    private final LongAdder _rejectedBySubnet = new LongAdder();
+    private final LongAdder _rejectedByGreylist = new LongAdder();
+    private final LongAdder _greylisted = new LongAdder();
+    private int _maxConnectionsPerAddress = -1;
+    private int _maxConnectionsPerSubnet = -1;
+    private int _ipv4SubnetPrefixLength = 24;
+    private int _ipv6SubnetPrefixLength = 64;
+    private int _maxAcceptRatePerAddress = -1;
+    private long _acceptRatePeriod = 1000;
+    private long _greylistPeriod = 60000;
+    private Scheduler.Task _task;
+
+    public ConnectionAdmissionControl(@Name("server") Server server)
+    {
+        _server = server;
+    }
+
+    public ConnectionAdmissionControl(@Name("connectors") Connector... connectors)
+    {
+        this((Server)null);
+        for (Connector c : connectors)
+        {
+            if (c instanceof AbstractConnector)
+                _connectors.add((AbstractConnector)c);
+            else
+                LOG.warn("Connector {} is not an AbstractConnector. Connections not limited", c);
+        }
+    }
+
+    /**
+     * @return the max number of connections per remote IP address, or a negative value for no limit
+     */
+    @ManagedAttribute("The maximum number of connections per remote IP address")
+    public int getMaxConnectionsPerAddress()
+    {
+        return _maxConnectionsPerAddress;
+    }
+
+    /**
+     * @param maxConnectionsPerAddress the max number of connections per remote IP address, or a negative value for no limit
+     */
+    public void setMaxConnectionsPerAddress(int maxConnectionsPerAddress)
+    {
+        _maxConnectionsPerAddress = maxConnectionsPerAddress;
+    }
+
+    /**
+     * @return the max number of connections per remote subnet, or a negative value for no limit
+     */
+    @ManagedAttribute("The maximum number of connections per remote subnet")
+    public int getMaxConnectionsPerSubnet()
+    {
+        return _maxConnectionsPerSubnet;
+    }
+
+    /**
+     * @param maxConnectionsPerSubnet the max number of connections per remote subnet, or a negative value for no limit
+     */
+    public void setMaxConnectionsPerSubnet(int maxConnectionsPerSubnet)
+    {
+        _maxConnectionsPerSubnet = maxConnectionsPerSubnet;
+    }
+
+    /**
+     * @return the number of leading bits of IPv4 addresses that identify a subnet
+     */
+    @ManagedAttribute("The prefix length of IPv4 subnets")
+    public int getIPv4SubnetPrefixLength()
+    {
+        return _ipv4SubnetPrefixLength;
+    }
+
+    /**
+     * @param prefixLength the number of leading bits of IPv4 addresses that identify a subnet
+     */
+    public void setIPv4SubnetPrefixLength(int prefixLength)
+    {
+        if (prefixLength < 0 || prefixLength > 32)
+            throw new IllegalArgumentException("Invalid IPv4 prefix length " + prefixLength);
+        _ipv4SubnetPrefixLength = prefixLength;
+    }
+
+    /**
+     * @return the number of leading bits of IPv6 addresses that identify a subnet
+     */
+    @ManagedAttribute("The prefix length of IPv6 subnets")
+    public int getIPv6SubnetPrefixLength()
+    {
+        return _ipv6SubnetPrefixLength;
+    }
+
+    /**
+     * @param prefixLength the number of leading bits of IPv6 addresses that identify a subnet
+     */
+    public void setIPv6SubnetPrefixLength(int prefixLength)
+    {
+        if (prefixLength < 0 || prefixLength > 128)
+            throw new IllegalArgumentException("Invalid IPv6 prefix length " + prefixLength);
+        _ipv6SubnetPrefixLength = prefixLength;
+    }
+
+    /**
+     * @return the max number of connections a remote IP address may open within
+     * the {@link #getAcceptRatePeriod() accept rate period} before being greylisted,
+     * or a negative value for no limit
+     */
+    @ManagedAttribute("The maximum number of connections accepted per remote IP address in the accept rate period")
+    public int getMaxAcceptRatePerAddress()
+    {
+        return _maxAcceptRatePerAddress;
+    }
+
+    /**
+     * @param maxAcceptRatePerAddress the max number of connections a remote IP address may open within
+     * the {@link #getAcceptRatePeriod() accept rate period} before being greylisted,
+     * or a negative value for no limit
+     */
+    public void setMaxAcceptRatePerAddress(int maxAcceptRatePerAddress)
+    {
+        _maxAcceptRatePerAddress = maxAcceptRatePerAddress;
+    }
+
+    /**
+     * @return the period in ms over which the per address accept rate is measured
+     */
+    @ManagedAttribute("The period in ms over which the per address accept rate is measured")
+    public long getAcceptRatePeriod()
+    {
+        return _acceptRatePeriod;
+    }
+
+    /**
+     * @param acceptRatePeriod the period in ms over which the per address accept rate is measured
+     */
+    public void setAcceptRatePeriod(long acceptRatePeriod)
+    {
+        if (acceptRatePeriod <= 0)
+            throw new IllegalArgumentException("Invalid accept rate period " + acceptRatePeriod);
+        _acceptRatePeriod = acceptRatePeriod;
+    }
+
+    /**
+     * @return the period in ms during which a greylisted remote IP address is rejected
+     */
+    @ManagedAttribute("The period in ms during which a greylisted remote IP address is rejected")
+    public long getGreylistPeriod()
+    {
+        return _greylistPeriod;
+    }
+
+    /**
+     * @param greylistPeriod the period in ms during which a greylisted remote IP address is rejected
+     */
+    public void setGreylistPeriod(long greylistPeriod)
+    {
+        _greylistPeriod = greylistPeriod;
+    }
+
+    @ManagedAttribute("The number of connections rejected because of the per address limit")
+    public long getRejectedByAddress()
+    {
+        return _rejectedByAddress.sum();
+    }
+
+    @ManagedAttribute("The number of connections rejected because of the per subnet limit")
+    public long getRejectedBySubnet()
+    {
+        return _rejectedBySubnet.sum();
+    }
+
+    @ManagedAttribute("The number of connections rejected because of greylisting")
+    public long getRejectedByGreylist()
+    {
+        return _rejectedByGreylist.sum();
+    }
+
+    @ManagedAttribute("The total number of connections rejected")
+    public long getRejected()
+    {
+        return getRejectedByAddress() + getRejectedBySubnet() + getRejectedByGreylist();
+    }
+
+    @ManagedAttribute("The number of times a remote IP address has been greylisted")
+    public long getGreylistedCount()
+    {
+        return _greylisted.sum();
+    }
+
+    @ManagedAttribute("The current number of admitted connections")
+    public int getConnections()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _admitted.size();
+        }
+    }
+
+    /**
+     * @param address the remote IP address
+     * @return the current number of connections from the given remote IP address
+     */
+    @ManagedOperation(value = "The current number of connections from a remote IP address", impact = "INFO")
+    public int getConnections(@Name("address") String address)
+    {
+        InetAddress inetAddress = toInetAddress(address);
+        try (AutoLock l = _lock.lock())
+        {
+            return _addressConnections.getOrDefault(inetAddress, 0);
+        }
+    }
+
+    /**
+     * @return the currently greylisted remote IP addresses
+     */
+    @ManagedAttribute("The currently greylisted remote IP addresses")
+    public List<String> getGreylist()
+    {
+        long now = NanoTime.now();
+        try (AutoLock l = _lock.lock())
+        {
+            List<String> result = new ArrayList<>();
+            for (Map.Entry<InetAddress, Long> entry : _greylist.entrySet())
+            {
+                if (NanoTime.isBefore(now, entry.getValue()))
+                    result.add(entry.getKey().getHostAddress());
+            }
+            return result;
+        }
+    }
+
+    /**
+     * <p>Greylists the given remote IP address for the {@link #getGreylistPeriod() greylist period}.</p>
+     *
+     * @param address the remote IP address to greylist
+     */
+    @ManagedOperation(value = "Greylists a remote IP address", impact = "ACTION")
+    public void greylist(@Name("address") String address)
+    {
+        InetAddress inetAddress = toInetAddress(address);
+        try (AutoLock l = _lock.lock())
+        {
+            greylist(inetAddress, NanoTime.now());
+        }
+    }
+
+    /**
+     * @param address the remote IP address to remove from the greylist
+     * @return whether the remote IP address was greylisted
+     */
+    @ManagedOperation(value = "Removes a remote IP address from the greylist", impact = "ACTION")
+    public boolean ungreylist(@Name("address") String address)
+    {
+        InetAddress inetAddress = toInetAddress(address);
+        try (AutoLock l = _lock.lock())
+        {
+            _acceptWindows.remove(inetAddress);
+            return _greylist.remove(inetAddress) != null;
+        }
+    }
+
+    @ManagedOperation(value = "Resets the greylist and the statistics", impact = "ACTION")
+    public void reset()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            _greylist.clear();
+            _acceptWindows.clear();
+        }
+        _rejectedByAddress.reset();
+        _rejectedBySubnet.reset();
+        _rejectedByGreylist.reset();
+        _greylisted.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (_server != null)
+            {
+                for (Connector c : _server.getConnectors())
+                {
+                    if (c instanceof AbstractConnector)
+                        _connectors.add((AbstractConnector)c);
+                    else
+                        LOG.warn("Connector {} is not an AbstractConnector. Connections not limited", c);
+                }
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("ConnectionAdmissionControl address<{} subnet<{} rate<{}/{}ms for {}",
+                    _maxConnectionsPerAddress, _maxConnectionsPerSubnet, _maxAcceptRatePerAddress, _acceptRatePeriod, _connectors);
+            for (AbstractConnector c : _connectors)
+            {
+                c.addBean(this);
+            }
+            if (!_connectors.isEmpty())
+                schedule();
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (_task != null)
+                _task.cancel();
+            _task = null;
+            for (AbstractConnector c : _connectors)
+            {
+                c.removeBean(this);
+            }
+            if (_server != null)
+                _connectors.clear();
+            _admitted.clear();
+            _addressConnections.clear();
+            _subnetConnections.clear();
+            _acceptWindows.clear();
+            _greylist.clear();
+        }
+    }
+
+    @Override
+    public void onAccepting(SelectableChannel channel)
+    {
+        InetAddress address = remoteAddress(channel);
+        if (address == null)
+            return;
+
+        Rejection rejection;
+        try (AutoLock l = _lock.lock())
+        {
+            rejection = admit(channel, address);
+        }
+
+        if (rejection != null)
+        {
+            switch (rejection)
+            {
+                case ADDRESS:
+                    _rejectedByAddress.increment();
+                    break;
+                case SUBNET:
+                    _rejectedBySubnet.increment();
+                    break;
+                default:
+                    _rejectedByGreylist.increment();
+                    break;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejected {} from {}: {}", channel, address, rejection);
+            // Closing the channel makes the SelectorManager fail the accept.
+            IO.close(channel);
+        }
+    }
+
+    private Rejection admit(SelectableChannel channel, InetAddress address)
+    {
+        long now = NanoTime.now();
+
+        Long greylisted = _greylist.get(address);
+        if (greylisted != null)
+        {
+            if (NanoTime.isBefore(now, greylisted))
+                return Rejection.GREYLIST;
+            _greylist.remove(address);
+        }
+
+        if (_maxAcceptRatePerAddress >= 0)
+        {
+            AcceptWindow window = _acceptWindows.computeIfAbsent(address, key -> new AcceptWindow(now));
+            if (window.record(now, TimeUnit.MILLISECONDS.toNanos(_acceptRatePeriod)) > _maxAcceptRatePerAddress)
+            {
+                LOG.warn("Accept rate exceeded {}>{} in {}ms for {}, greylisting for {}ms",
+                    window.count, _maxAcceptRatePerAddress, _acceptRatePeriod, address.getHostAddress(), _greylistPeriod);
+                greylist(address, now);
+                return Rejection.GREYLIST;
+            }
+        }
+
+        int addressConnections = _addressConnections.getOrDefault(address, 0);
+        if (_maxConnectionsPerAddress >= 0 && addressConnections >= _maxConnectionsPerAddress)
+            return Rejection.ADDRESS;
+
+        InetAddress subnet = subnet(address);
+        int subnetConnections = _subnetConnections.getOrDefault(subnet, 0);
+        if (_maxConnectionsPerSubnet >= 0 && subnetConnections >= _maxConnectionsPerSubnet)
+            return Rejection.SUBNET;
+
+        _addressConnections.put(address, addressConnections + 1);
+        _subnetConnections.put(subnet, subnetConnections + 1);
+        _admitted.put(channel, new Admission(address, subnet));
+        return null;
+    }
+
+    private void greylist(InetAddress address, long now)
+    {
+        _greylist.put(address, now + TimeUnit.MILLISECONDS.toNanos(_greylistPeriod));
+        _acceptWindows.remove(address);
+        _greylisted.increment();
+    }
+
+    @Override
+    public void onAcceptFailed(SelectableChannel channel, Throwable cause)
+    {
+        release(channel);
+    }
+
+    @Override
+    public void onOpened(Connection connection)
+    {
+    }
+
+    @Override
+    public void onClosed(Connection connection)
+    {
+        Object transport = connection.getEndPoint().getTransport();
+        if (transport instanceof SelectableChannel)
+            release((SelectableChannel)transport);
+    }
+
+    private void release(SelectableChannel channel)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            Admission admission = _admitted.remove(channel);
+            if (admission == null)
+                return;
+            _addressConnections.computeIfPresent(admission.address, (key, count) -> count > 1 ? count - 1 : null);
+            _subnetConnections.computeIfPresent(admission.subnet, (key, count) -> count > 1 ? count - 1 : null);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Released {} from {}", channel, admission.address);
+        }
+    }
+
+    private void schedule()
+    {
+        long delay = Math.max(_acceptRatePeriod, 1000);
+        _task = _connectors.get(0).getScheduler().schedule(this, delay, TimeUnit.MILLISECONDS);
+    }
+
+    /**
+     * <p>Periodically discards expired greylist entries and stale accept rate measurements.</p>
+     */
+    @Override
+    public void run()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            _task = null;
+            if (!isRunning())
+                return;
+            long now = NanoTime.now();
+            _greylist.values().removeIf(expires -> NanoTime.isBeforeOrSame(expires, now));
+            long period = TimeUnit.MILLISECONDS.toNanos(_acceptRatePeriod);
+            for (Iterator<AcceptWindow> i = _acceptWindows.values().iterator(); i.hasNext();)
+            {
+                if (NanoTime.elapsed(i.next().begin, now) >= period)
+                    i.remove();
+            }
+            schedule();
+        }
+    }
+
+    private InetAddress subnet(InetAddress address)
+    {
+        byte[] bytes = address.getAddress();
+        int prefixLength = bytes.length == 4 ? _ipv4SubnetPrefixLength : _ipv6SubnetPrefixLength;
+        for (int i = 0; i < bytes.length; ++i)
+        {
+            int bits = prefixLength - i * 8;
+            if (bits <= 0)
+                bytes[i] = 0;
+            else if (bits < 8)
+                bytes[i] &= (byte)(0xFF << (8 - bits));
+        }
+        try
+        {
+            return InetAddress.getByAddress(bytes);
+        }
+        catch (UnknownHostException x)
+        {
+            // Cannot happen, the length of the address is valid.
+            return address;
+        }
+    }
+
+    private static InetAddress remoteAddress(SelectableChannel channel)
+    {
+        try
+        {
+            if (channel instanceof SocketChannel)
+            {
+                SocketAddress remote = ((SocketChannel)channel).getRemoteAddress();
+                if (remote instanceof InetSocketAddress)
+                    return ((InetSocketAddress)remote).getAddress();
+            }
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not get remote address for {}", channel, x);
+        }
+        return null;
+    }
+
+    private static InetAddress toInetAddress(String address)
+    {
+        try
+        {
+            // Only IP literals are valid, so no name resolution is performed.
+            if (!Character.isDigit(address.charAt(0)) && address.indexOf(':') < 0)
+                throw new IllegalArgumentException("Not an IP address: " + address);
+            return InetAddress.getByName(address);
+        }
+        catch (UnknownHostException x)
+        {
+            throw new IllegalArgumentException("Not an IP address: " + address, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[address<%d,subnet<%d,rate<%d/%dms,rejected=%d]",
+            getClass().getSimpleName(),
+            hashCode(),
+            _maxConnectionsPerAddress,
+            _maxConnectionsPerSubnet,
+            _maxAcceptRatePerAddress,
+            _acceptRatePeriod,
+            getRejected());
+    }
+
+    private enum Rejection
+    {
+        ADDRESS, SUBNET, GREYLIST
+    }
+
+    private static class Admission
+    {
+        private final InetAddress address;
+        private final InetAddress subnet;
+
+        private Admission(InetAddress address, InetAddress subnet)
+        {
+            this.address = address;
+            this.subnet = subnet;
+        }
+    }
+
+    private static class AcceptWindow
+    {
+        private long begin;
+        private int count;
+
+        private AcceptWindow(long begin)
+        {
+            this.begin = begin;
+        }
+
+        private int record(long now, long period)
+        {
+            if (NanoTime.elapsed(begin, now) >= period)
+            {
+                begin = now;
+                count = 0;
+            }
+            return ++count;
+        }
+    }
+}
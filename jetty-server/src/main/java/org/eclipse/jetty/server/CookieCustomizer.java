@@ -0,0 +1,125 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.nio.ByteBuffer;
+import java.util.Objects;
+
+import org.eclipse.jetty.http.HttpCookie;
+import org.eclipse.jetty.util.Callback;
+
+/**
+ * <p>Rewrites the response cookies just before the response is committed.</p>
+ * <p>This customizer allows to enforce a cookie policy, for example to add the
+ * {@code HttpOnly} attribute or to rename the session cookie with a {@code __Host-} prefix,
+ * regardless of how the cookies were added by the application:</p>
+ * <pre>{@code
+ * httpConfig.addCustomizer(new CookieCustomizer((request, cookie) ->
+ *     cookie.isHttpOnly() ? cookie : HttpCookie.build(cookie).httpOnly(true).build()));
+ * }</pre>
+ *
+ * @see Response#rewriteCookies(java.util.function.UnaryOperator)
+ */
+public class CookieCustomizer implements HttpConfiguration.Customizer
+{
+    private final Rewriter rewriter;
+
+    /**
+     * Constructs a customizer for subclasses that override {@link #rewrite(Request, HttpCookie)}.
+     */
+    protected CookieCustomizer()
+    {
+        rewriter = null;
+    }
+
+    /**
+     * @param rewriter the rewriter of the response cookies
+     */
+    public CookieCustomizer(Rewriter rewriter)
+    {
+        this.rewriter = Objects.requireNonNull(rewriter);
+    }
+
+    @Override
+    public void customize(Connector connector, HttpConfiguration channelConfig, Request request)
+    {
+        HttpOutput out = request.getResponse().getHttpOutput();
+        out.setInterceptor(new CookieInterceptor(request, out.getInterceptor()));
+    }
+
+    /**
+     * @param request the request
+     * @param cookie a response cookie
+     * @return the same cookie to leave it unchanged, a new cookie to replace it, or null to remove it
+     */
+    protected HttpCookie rewrite(Request request, HttpCookie cookie)
+    {
+        return rewriter == null ? cookie : rewriter.rewrite(request, cookie);
+    }
+
+    /**
+     * <p>The rewriter of the response cookies.</p>
+     */
+    @FunctionalInterface
+    public interface Rewriter
+    {
+        /**
+         * @param request the request
+         * @param cookie a response cookie
+         * @return the same cookie to leave it unchanged, a new cookie to replace it, or null to remove it
+         */
+        HttpCookie rewrite(Request request, HttpCookie cookie);
+    }
+
+    /**
+     * Rewrites the cookies on the first write, which commits the response
+     * since this interceptor is the closest to the channel.
+     */
+    private class CookieInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request request;
+        private final HttpOutput.Interceptor next;
+
+        private CookieInterceptor(Request request, HttpOutput.Interceptor next)
+        {
+            this.request = request;
+            this.next = next;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return next;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            Response response = request.getResponse();
+            if (!response.isCommitted())
+            {
+                try
+                {
+                    response.rewriteCookies(cookie -> rewrite(request, cookie));
+                }
+                catch (Throwable x)
+                {
+                    callback.failed(x);
+                    return;
+                }
+            }
+            next.write(content, last, callback);
+        }
+    }
+}
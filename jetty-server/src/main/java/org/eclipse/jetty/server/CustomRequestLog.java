@@ -205,6 +205,14 @@ import static java.lang.invoke.MethodType.methodType;
  * </tr>
  *
  * <tr>
+ * <td>%{VARNAME}n</td>
+ * <td>
+ * <p>The value of the VARNAME request attribute, for example
+ * {@code %{org.eclipse.jetty.server.tls_ja4}n} for the JA4 fingerprint of the TLS client.</p>
+ * </td>
+ * </tr>
+ *
+ * <tr>
  * <td>%{VARNAME}o</td>
  * <td>
  * <p>The value of the VARNAME response header.</p>
@@ -823,6 +831,16 @@ public class CustomRequestLog extends ContainerLifeCycle implements RequestLog
                 break;
             }
 
+            case "n":
+            {
+                if (StringUtil.isEmpty(arg))
+                    throw new IllegalArgumentException("No arg for %n");
+
+                specificHandle = lookup.findStatic(CustomRequestLog.class, "logRequestAttribute", logTypeArg);
+                specificHandle = specificHandle.bindTo(arg);
+                break;
+            }
+
             case "o":
             {
                 if (StringUtil.isEmpty(arg))
@@ -1195,6 +1213,13 @@ public class CustomRequestLog extends ContainerLifeCycle implements RequestLog
         append(b, request.getMethod());
     }
 
+    @SuppressWarnings("unused")
+    private static void logRequestAttribute(String arg, StringBuilder b, Request request, Response response)
+    {
+        Object value = request.getAttribute(arg);
+        append(b, value == null ? null : value.toString());
+    }
+
     @SuppressWarnings("unused")
     private static void logResponseHeader(String arg, StringBuilder b, Request request, Response response)
     {
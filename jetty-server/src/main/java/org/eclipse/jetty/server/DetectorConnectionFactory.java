@@ -23,18 +23,27 @@ import org.eclipse.jetty.io.AbstractConnection;
 import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 /**
  * A {@link ConnectionFactory} combining multiple {@link Detecting} instances that will upgrade to
  * the first one recognizing the bytes in the buffer.
+ * <p>Detectors can be composed, for example to detect the PROXY protocol followed by either
+ * TLS, HTTP/1.1 or the HTTP/2 cleartext preface on the same port.</p>
+ * <p>When a {@link #setDetectionTimeout(long) detection timeout} is configured and the bytes
+ * are not recognized within that time, for example because the client is waiting for the server
+ * to speak first, or because it sent too few bytes, the detection falls back to the
+ * {@link #setFallbackProtocol(String) fallback protocol}.</p>
  */
 public class DetectorConnectionFactory extends AbstractConnectionFactory implements ConnectionFactory.Detecting
 {
     private static final Logger LOG = LoggerFactory.getLogger(DetectorConnectionFactory.class);
 
     private final List<Detecting> _detectingConnectionFactories;
+    private long _detectionTimeout;
+    private String _fallbackProtocol;
 
     /**
      * <p>When the first bytes are not recognized by the {@code detectingConnectionFactories}, the default behavior is to
@@ -51,6 +60,49 @@ public class DetectorConnectionFactory extends AbstractConnectionFactory impleme
         }
     }
 
+    /**
+     * @return the max time in milliseconds to wait for the bytes to be recognized,
+     * or zero to wait until the connector idle timeout expires
+     */
+    @ManagedAttribute("The max time in milliseconds to wait for the bytes to be recognized")
+    public long getDetectionTimeout()
+    {
+        return _detectionTimeout;
+    }
+
+    /**
+     * <p>Sets the max time to wait for the bytes to be recognized, after which the
+     * detection falls back to the {@link #getFallbackProtocol() fallback protocol}
+     * with the bytes received so far.</p>
+     *
+     * @param detectionTimeout the detection timeout in milliseconds, or zero to wait
+     * until the connector idle timeout expires and the connection is closed
+     */
+    public void setDetectionTimeout(long detectionTimeout)
+    {
+        _detectionTimeout = detectionTimeout;
+    }
+
+    /**
+     * @return the protocol to upgrade to when the bytes are not recognized,
+     * or null to use the protocol following this one in the connector
+     */
+    @ManagedAttribute("The protocol to upgrade to when the bytes are not recognized")
+    public String getFallbackProtocol()
+    {
+        return _fallbackProtocol;
+    }
+
+    /**
+     * @param fallbackProtocol the protocol to upgrade to when the bytes are not recognized,
+     * or null to use the protocol following this one in the connector
+     * @see #nextProtocol(Connector, EndPoint, ByteBuffer)
+     */
+    public void setFallbackProtocol(String fallbackProtocol)
+    {
+        _fallbackProtocol = fallbackProtocol;
+    }
+
     private static String toProtocolString(Detecting... detectingConnectionFactories)
     {
         if (detectingConnectionFactories.length == 0)
@@ -113,15 +165,16 @@ public class DetectorConnectionFactory extends AbstractConnectionFactory impleme
     }
 
     /**
-     * <p>Callback method called when detection was unsuccessful.
-     * This implementation upgrades to the protocol returned by {@link #findNextProtocol(Connector)}.</p>
+     * <p>Callback method called when detection was unsuccessful, or timed out.
+     * This implementation upgrades to the {@link #getFallbackProtocol() fallback protocol}
+     * if configured, otherwise to the protocol returned by {@link #findNextProtocol(Connector)}.</p>
      * @param connector the connector.
      * @param endPoint the endpoint.
      * @param buffer the buffer.
      */
     protected void nextProtocol(Connector connector, EndPoint endPoint, ByteBuffer buffer) throws IllegalStateException
     {
-        String nextProtocol = findNextProtocol(connector);
+        String nextProtocol = _fallbackProtocol != null ? _fallbackProtocol : findNextProtocol(connector);
         if (LOG.isDebugEnabled())
             LOG.debug("Detector {} detection unsuccessful, found '{}' as the next protocol to upgrade to", getProtocol(), nextProtocol);
         if (nextProtocol == null)
@@ -139,6 +192,7 @@ public class DetectorConnectionFactory extends AbstractConnectionFactory impleme
     {
         private final Connector _connector;
         private final ByteBuffer _buffer;
+        private long _idleTimeout = -1;
 
         private DetectorConnection(EndPoint endp, Connector connector)
         {
@@ -158,6 +212,9 @@ public class DetectorConnectionFactory extends AbstractConnectionFactory impleme
         @Override
         public ByteBuffer onUpgradeFrom()
         {
+            // Restore the idle timeout for the next connection.
+            if (_idleTimeout >= 0)
+                getEndPoint().setIdleTimeout(_idleTimeout);
             if (_buffer.hasRemaining())
             {
                 ByteBuffer unconsumed = ByteBuffer.allocateDirect(_buffer.remaining());
@@ -173,10 +230,36 @@ public class DetectorConnectionFactory extends AbstractConnectionFactory impleme
         public void onOpen()
         {
             super.onOpen();
+            long detectionTimeout = getDetectionTimeout();
+            if (detectionTimeout > 0)
+            {
+                _idleTimeout = getEndPoint().getIdleTimeout();
+                getEndPoint().setIdleTimeout(detectionTimeout);
+            }
             if (!detectAndUpgrade())
                 fillInterested();
         }
 
+        @Override
+        protected boolean onReadTimeout(Throwable timeout)
+        {
+            if (_idleTimeout < 0)
+                return super.onReadTimeout(timeout);
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Detector {} detection timed out with {} bytes, falling back to nextProtocol()", getProtocol(), _buffer.remaining());
+            try
+            {
+                nextProtocol(_connector, getEndPoint(), _buffer);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Detector {} error for {}", getProtocol(), getEndPoint(), x);
+                releaseAndClose();
+            }
+            return false;
+        }
+
         @Override
         public void onFillable()
         {
@@ -250,6 +250,15 @@ public class Dispatcher implements RequestDispatcher
             if (illegalState != null)
                 throw new IllegalStateException(illegalState);
         }
+
+        try
+        {
+            baseRequest.canonicalizePath(uri.getPath());
+        }
+        catch (BadMessageException x)
+        {
+            throw new IllegalStateException(x.getReason(), x);
+        }
     }
 
     @Override
@@ -0,0 +1,721 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayDeque;
+import java.util.Deque;
+import java.util.Enumeration;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.AsyncContext;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletOutputStream;
+import javax.servlet.WriteListener;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpHeaderValue;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A helper to implement the server side of the
+ * <a href="https://html.spec.whatwg.org/multipage/server-sent-events.html">Server-Sent Events</a>
+ * protocol from a {@link org.eclipse.jetty.server.Handler}.</p>
+ * <p>A handler calls {@link #accept(Request, HttpServletResponse, Listener)} for
+ * requests that {@link #isEventStream(HttpServletRequest) accept} {@code text/event-stream},
+ * obtaining a {@link Client} that can be used to send events, possibly from other threads:</p>
+ * <pre>
+ * public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+ * {
+ *     if (EventSourceServer.isEventStream(request))
+ *         eventSourceServer.accept(baseRequest, response, client -&gt; replayFrom(client.getLastEventId()));
+ * }
+ * </pre>
+ * <p>Each client has a send queue of at most {@link #getMaxQueueSize()} events, that
+ * is written only when the response output is ready, so that slow clients do not
+ * block the senders; when the queue is full, the {@link #getOverflowPolicy() overflow policy}
+ * determines whether the oldest event, or the new event, is dropped, or the client is closed.</p>
+ * <p>Heartbeat comments are sent every {@link #getHeartBeatPeriod()} milliseconds
+ * while the queue is empty, to keep the connection alive through intermediaries
+ * and to detect clients that went away.</p>
+ */
+@ManagedObject("Server-Sent Events server")
+public class EventSourceServer extends ContainerLifeCycle
+{
+    public static final String TEXT_EVENT_STREAM = "text/event-stream";
+    public static final String LAST_EVENT_ID = "Last-Event-ID";
+
+    private static final Logger LOG = LoggerFactory.getLogger(EventSourceServer.class);
+    private static final byte[] HEARTBEAT = ":\n\n".getBytes(StandardCharsets.UTF_8);
+
+    private final Set<Client> _clients = ConcurrentHashMap.newKeySet();
+    private long _heartBeatPeriod = 10000;
+    private long _retry = -1;
+    private int _maxQueueSize = 1024;
+    private OverflowPolicy _overflowPolicy = OverflowPolicy.DROP_OLDEST;
+
+    /**
+     * @param request the request
+     * @return whether the request accepts {@code text/event-stream} responses
+     */
+    public static boolean isEventStream(HttpServletRequest request)
+    {
+        Enumeration<String> accepts = request.getHeaders(HttpHeader.ACCEPT.asString());
+        while (accepts.hasMoreElements())
+        {
+            String accept = accepts.nextElement();
+            if (accept.contains(TEXT_EVENT_STREAM))
+                return true;
+        }
+        return false;
+    }
+
+    @ManagedAttribute("The heartbeat period in ms, or <= 0 to disable heartbeats")
+    public long getHeartBeatPeriod()
+    {
+        return _heartBeatPeriod;
+    }
+
+    public void setHeartBeatPeriod(long heartBeatPeriod)
+    {
+        _heartBeatPeriod = heartBeatPeriod;
+    }
+
+    @ManagedAttribute("The reconnection time in ms sent to clients, or < 0 to not send it")
+    public long getRetry()
+    {
+        return _retry;
+    }
+
+    public void setRetry(long retry)
+    {
+        _retry = retry;
+    }
+
+    @ManagedAttribute("The max number of events queued per client")
+    public int getMaxQueueSize()
+    {
+        return _maxQueueSize;
+    }
+
+    public void setMaxQueueSize(int maxQueueSize)
+    {
+        if (maxQueueSize <= 0)
+            throw new IllegalArgumentException("Invalid max queue size " + maxQueueSize);
+        _maxQueueSize = maxQueueSize;
+    }
+
+    @ManagedAttribute("The policy applied when a client queue is full")
+    public OverflowPolicy getOverflowPolicy()
+    {
+        return _overflowPolicy;
+    }
+
+    public void setOverflowPolicy(OverflowPolicy overflowPolicy)
+    {
+        _overflowPolicy = Objects.requireNonNull(overflowPolicy);
+    }
+
+    @ManagedAttribute("The number of connected clients")
+    public int getClientCount()
+    {
+        return _clients.size();
+    }
+
+    /**
+     * @return the connected clients
+     */
+    public Set<Client> getClients()
+    {
+        return Set.copyOf(_clients);
+    }
+
+    /**
+     * <p>Accepts the given request as an event stream, committing the response
+     * headers and completing the request only when the client is closed.</p>
+     *
+     * @param baseRequest the request
+     * @param response the response
+     * @param listener the listener notified of the client events
+     * @return the client, open
+     * @throws IOException if the response cannot be committed
+     */
+    public Client accept(Request baseRequest, HttpServletResponse response, Listener listener) throws IOException
+    {
+        baseRequest.setHandled(true);
+        response.setStatus(HttpServletResponse.SC_OK);
+        response.setCharacterEncoding(StandardCharsets.UTF_8.name());
+        response.setContentType(TEXT_EVENT_STREAM);
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), HttpHeaderValue.NO_CACHE.asString());
+
+        AsyncContext asyncContext = baseRequest.startAsync();
+        // The request is only completed when the client is closed.
+        asyncContext.setTimeout(0);
+        Client client = new Client(baseRequest, asyncContext, listener);
+        _clients.add(client);
+        asyncContext.addListener(client);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Accepted {}", client);
+
+        // Events sent before the WriteListener is installed are only queued,
+        // and the first callback to onWritePossible() writes them and commits
+        // the response.
+        if (_retry >= 0)
+            client.send(new Event().retry(_retry));
+        listener.onOpen(client);
+        client._output.setWriteListener(client);
+        client.scheduleHeartBeat();
+        return client;
+    }
+
+    /**
+     * @param event the event to send to all connected clients
+     */
+    public void broadcast(Event event)
+    {
+        for (Client client : _clients)
+        {
+            client.send(event);
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        for (Client client : _clients)
+        {
+            client.close();
+        }
+        super.doStop();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[clients=%d]", getClass().getSimpleName(), hashCode(), _clients.size());
+    }
+
+    /**
+     * The policy applied when the send queue of a client is full.
+     */
+    public enum OverflowPolicy
+    {
+        /**
+         * The oldest queued event is dropped.
+         */
+        DROP_OLDEST,
+        /**
+         * The new event is dropped.
+         */
+        DROP_NEWEST,
+        /**
+         * The client is closed.
+         */
+        CLOSE
+    }
+
+    /**
+     * A listener for client events.
+     */
+    @FunctionalInterface
+    public interface Listener
+    {
+        /**
+         * <p>Invoked when a client is accepted, before the response is committed.</p>
+         * <p>Events sent from this method, for example to replay the events after
+         * {@link Client#getLastEventId()}, are sent before any broadcast event.</p>
+         *
+         * @param client the client
+         */
+        void onOpen(Client client);
+
+        /**
+         * <p>Invoked when a client is closed, either explicitly or because the
+         * connection failed.</p>
+         *
+         * @param client the client
+         */
+        default void onClose(Client client)
+        {
+        }
+    }
+
+    /**
+     * <p>An event of the event stream.</p>
+     * <p>The fields are written in the order id, event, retry, data;
+     * multi-line data is written as multiple {@code data} fields.</p>
+     */
+    public static class Event
+    {
+        private String _id;
+        private String _name;
+        private long _retry = -1;
+        private String _data;
+
+        public Event()
+        {
+        }
+
+        public Event(String data)
+        {
+            data(data);
+        }
+
+        public Event id(String id)
+        {
+            _id = checkField(id);
+            return this;
+        }
+
+        public Event name(String name)
+        {
+            _name = checkField(name);
+            return this;
+        }
+
+        public Event retry(long retry)
+        {
+            _retry = retry;
+            return this;
+        }
+
+        public Event data(String data)
+        {
+            _data = data;
+            return this;
+        }
+
+        public String getId()
+        {
+            return _id;
+        }
+
+        public String getName()
+        {
+            return _name;
+        }
+
+        public long getRetry()
+        {
+            return _retry;
+        }
+
+        public String getData()
+        {
+            return _data;
+        }
+
+        private static String checkField(String value)
+        {
+            if (value != null && (value.indexOf('\n') >= 0 || value.indexOf('\r') >= 0))
+                throw new IllegalArgumentException("Invalid field value " + value);
+            return value;
+        }
+
+        /**
+         * @return the event serialized in the {@code text/event-stream} format
+         */
+        public byte[] toBytes()
+        {
+            StringBuilder builder = new StringBuilder();
+            if (_id != null)
+                builder.append("id: ").append(_id).append('\n');
+            if (_name != null)
+                builder.append("event: ").append(_name).append('\n');
+            if (_retry >= 0)
+                builder.append("retry: ").append(_retry).append('\n');
+            if (_data != null)
+            {
+                for (String line : _data.split("\r\n|\r|\n", -1))
+                {
+                    builder.append("data: ").append(line).append('\n');
+                }
+            }
+            builder.append('\n');
+            return builder.toString().getBytes(StandardCharsets.UTF_8);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[id=%s,name=%s]", getClass().getSimpleName(), hashCode(), _id, _name);
+        }
+    }
+
+    /**
+     * <p>A connected event stream client.</p>
+     * <p>Events are queued and written when the response output is ready,
+     * and the output is flushed every time the queue is drained.</p>
+     */
+    public class Client implements WriteListener, AsyncListener, Runnable
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final Deque<byte[]> _queue = new ArrayDeque<>();
+        private final Request _request;
+        private final AsyncContext _asyncContext;
+        private final ServletOutputStream _output;
+        private final Listener _listener;
+        private final Scheduler _scheduler;
+        private String _lastEventId;
+        private Scheduler.Task _heartBeat;
+        // Whether there is a write loop, either running or waiting for a callback to onWritePossible();
+        // initially waiting because the container calls onWritePossible() after setWriteListener().
+        private boolean _writing = true;
+        private boolean _waiting = true;
+        private boolean _writePossible;
+        private boolean _flush = true;
+        private boolean _closed;
+        private long _dropped;
+
+        private Client(Request request, AsyncContext asyncContext, Listener listener) throws IOException
+        {
+            _request = request;
+            _asyncContext = asyncContext;
+            _output = asyncContext.getResponse().getOutputStream();
+            _listener = Objects.requireNonNull(listener);
+            _scheduler = request.getHttpChannel().getScheduler();
+            _lastEventId = request.getHeader(LAST_EVENT_ID);
+        }
+
+        /**
+         * @return the request of this client
+         */
+        public Request getRequest()
+        {
+            return _request;
+        }
+
+        /**
+         * @return the id of the last event sent to this client, initially
+         * the value of the {@code Last-Event-ID} request header sent by
+         * reconnecting clients, or null
+         */
+        public String getLastEventId()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _lastEventId;
+            }
+        }
+
+        /**
+         * @return the number of events queued
+         */
+        public int getQueueSize()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _queue.size();
+            }
+        }
+
+        /**
+         * @return the number of events dropped because the queue was full
+         */
+        public long getDroppedEvents()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _dropped;
+            }
+        }
+
+        public boolean isOpen()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return !_closed;
+            }
+        }
+
+        /**
+         * @param data the data of the event to send
+         * @return whether the event was queued
+         * @see #send(Event)
+         */
+        public boolean send(String data)
+        {
+            return send(new Event(data));
+        }
+
+        /**
+         * <p>Queues the given event to be sent to this client.</p>
+         *
+         * @param event the event to send
+         * @return whether the event was queued, false if the client is closed
+         * or if the event was dropped by the overflow policy
+         */
+        public boolean send(Event event)
+        {
+            return enqueue(event.toBytes(), event.getId(), false);
+        }
+
+        /**
+         * @param comment the comment to send, that is ignored by clients
+         * @return whether the comment was queued
+         */
+        public boolean comment(String comment)
+        {
+            String value = ": " + comment.replaceAll("\r\n|\r|\n", " ") + "\n\n";
+            return enqueue(value.getBytes(StandardCharsets.UTF_8), null, false);
+        }
+
+        private boolean enqueue(byte[] bytes, String id, boolean heartBeat)
+        {
+            boolean overflow = false;
+            boolean write = false;
+            try (AutoLock l = _lock.lock())
+            {
+                if (_closed)
+                    return false;
+                if (heartBeat && !_queue.isEmpty())
+                    return false;
+                if (_queue.size() >= _maxQueueSize)
+                {
+                    switch (_overflowPolicy)
+                    {
+                        case DROP_OLDEST:
+                            _queue.pollFirst();
+                            ++_dropped;
+                            break;
+                        case DROP_NEWEST:
+                            ++_dropped;
+                            return false;
+                        case CLOSE:
+                            overflow = true;
+                            break;
+                        default:
+                            throw new IllegalStateException();
+                    }
+                }
+                if (!overflow)
+                {
+                    _queue.offer(bytes);
+                    if (id != null)
+                        _lastEventId = id;
+                    if (!_writing)
+                    {
+                        _writing = true;
+                        write = true;
+                    }
+                }
+            }
+
+            if (overflow)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Queue overflow {}", this);
+                close();
+                return false;
+            }
+
+            if (write)
+            {
+                try
+                {
+                    write();
+                }
+                catch (Throwable x)
+                {
+                    onError(x);
+                }
+            }
+            return true;
+        }
+
+        @Override
+        public void onWritePossible() throws IOException
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_waiting)
+                {
+                    // Resume the write loop that was waiting for this callback.
+                    _waiting = false;
+                }
+                else if (_writing)
+                {
+                    // The write loop is running, let it know that it can write.
+                    _writePossible = true;
+                    return;
+                }
+                else
+                {
+                    _writing = true;
+                }
+            }
+            write();
+        }
+
+        private void write() throws IOException
+        {
+            while (true)
+            {
+                if (!_output.isReady())
+                {
+                    try (AutoLock l = _lock.lock())
+                    {
+                        if (!_writePossible)
+                        {
+                            _waiting = true;
+                            return;
+                        }
+                        _writePossible = false;
+                    }
+                    continue;
+                }
+
+                byte[] bytes;
+                try (AutoLock l = _lock.lock())
+                {
+                    if (_closed)
+                        return;
+                    bytes = _queue.poll();
+                    if (bytes == null)
+                    {
+                        if (!_flush)
+                        {
+                            _writing = false;
+                            _writePossible = false;
+                            return;
+                        }
+                        _flush = false;
+                    }
+                    else
+                    {
+                        _flush = true;
+                    }
+                }
+
+                if (bytes == null)
+                    _output.flush();
+                else
+                    _output.write(bytes);
+            }
+        }
+
+        @Override
+        public void onError(Throwable failure)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failure {}", this, failure);
+            close();
+        }
+
+        /**
+         * <p>Sends a heartbeat, if the queue is empty, and reschedules the next one.</p>
+         */
+        @Override
+        public void run()
+        {
+            enqueue(HEARTBEAT, null, true);
+            scheduleHeartBeat();
+        }
+
+        private void scheduleHeartBeat()
+        {
+            long period = getHeartBeatPeriod();
+            if (period <= 0)
+                return;
+            try (AutoLock l = _lock.lock())
+            {
+                if (!_closed)
+                    _heartBeat = _scheduler.schedule(this, period, TimeUnit.MILLISECONDS);
+            }
+        }
+
+        /**
+         * <p>Closes this client, discarding the queued events, and completes the request.</p>
+         */
+        public void close()
+        {
+            Scheduler.Task heartBeat;
+            try (AutoLock l = _lock.lock())
+            {
+                if (_closed)
+                    return;
+                _closed = true;
+                _queue.clear();
+                heartBeat = _heartBeat;
+                _heartBeat = null;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Closing {}", this);
+            if (heartBeat != null)
+                heartBeat.cancel();
+            _clients.remove(this);
+            try
+            {
+                _asyncContext.complete();
+            }
+            catch (IllegalStateException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Already completed {}", this, x);
+            }
+            try
+            {
+                _listener.onClose(this);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", _listener, x);
+            }
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            close();
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+            close();
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+            onError(event.getThrowable());
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public String toString()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return String.format("%s@%x[queued=%d,closed=%b,lastEventId=%s]", getClass().getSimpleName(), hashCode(), _queue.size(), _closed, _lastEventId);
+            }
+        }
+    }
+}
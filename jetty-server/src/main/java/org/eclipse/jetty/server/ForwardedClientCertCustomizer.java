@@ -0,0 +1,200 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.ByteArrayInputStream;
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.security.cert.CertificateException;
+import java.security.cert.CertificateFactory;
+import java.security.cert.X509Certificate;
+import java.util.ArrayList;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Set;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.StructuredFields;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.util.Attributes;
+import org.eclipse.jetty.util.InetAddressSet;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import static org.eclipse.jetty.server.ClientCertHeadersCustomizer.CLIENT_CERT;
+import static org.eclipse.jetty.server.ClientCertHeadersCustomizer.CLIENT_CERT_CHAIN;
+
+/**
+ * <p>Customizer for servers behind a TLS-terminating proxy, that reconstitutes the
+ * TLS client certificate chain from the {@code Client-Cert} and {@code Client-Cert-Chain}
+ * request headers specified by <a href="https://www.rfc-editor.org/rfc/rfc9440">RFC 9440</a>,
+ * and sets it as the {@value SecureRequestCustomizer#JAVAX_SERVLET_REQUEST_X_509_CERTIFICATE}
+ * request attribute, so that for example {@code CLIENT-CERT} authentication works.</p>
+ * <p>The headers are only used if the request was received from one of the
+ * {@link #addTrustedProxy(String) trusted proxies}; otherwise they are removed
+ * from the request and ignored.  If no trusted proxy is configured, the headers
+ * are never used.</p>
+ * <p>Headers from a trusted proxy that cannot be parsed are rejected with a 400 response.</p>
+ * <p>When the connection from the proxy uses TLS, this customizer must be added after
+ * {@link SecureRequestCustomizer}, so that the forwarded certificate chain shadows the
+ * one of the proxy.</p>
+ *
+ * @see ClientCertHeadersCustomizer
+ */
+public class ForwardedClientCertCustomizer implements HttpConfiguration.Customizer
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ForwardedClientCertCustomizer.class);
+
+    private final InetAddressSet trustedProxies = new InetAddressSet();
+
+    /**
+     * <p>Adds an address pattern, in the format supported by {@link InetAddressSet},
+     * of the proxies that are trusted to add the {@code Client-Cert} headers.</p>
+     *
+     * @param inetAddressPattern the address pattern of trusted proxies
+     */
+    public void addTrustedProxy(String inetAddressPattern)
+    {
+        trustedProxies.add(inetAddressPattern);
+    }
+
+    /**
+     * @param inetAddressPatterns the address patterns of trusted proxies
+     * @see #addTrustedProxy(String)
+     */
+    public void addTrustedProxies(String... inetAddressPatterns)
+    {
+        for (String inetAddressPattern : inetAddressPatterns)
+        {
+            addTrustedProxy(inetAddressPattern);
+        }
+    }
+
+    /**
+     * @return the address patterns of trusted proxies
+     */
+    public InetAddressSet getTrustedProxies()
+    {
+        return trustedProxies;
+    }
+
+    @Override
+    public void customize(Connector connector, HttpConfiguration channelConfig, Request request)
+    {
+        HttpFields httpFields = request.getHttpFields();
+        String clientCert = httpFields.get(CLIENT_CERT);
+        String clientCertChain = httpFields.get(CLIENT_CERT_CHAIN);
+        if (clientCert == null && clientCertChain == null)
+            return;
+
+        if (!isTrustedProxy(request))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Ignoring {} headers from untrusted peer {}", CLIENT_CERT, request.getHttpChannel().getEndPoint());
+            HttpFields.Mutable fields = HttpFields.build(httpFields);
+            fields.remove(CLIENT_CERT);
+            fields.remove(CLIENT_CERT_CHAIN);
+            request.setHttpFields(fields);
+            return;
+        }
+
+        // A chain without the client certificate is ignored.
+        if (clientCert == null)
+            return;
+
+        try
+        {
+            CertificateFactory certificateFactory = CertificateFactory.getInstance("X.509");
+            List<X509Certificate> certs = new ArrayList<>();
+            certs.add(toCertificate(certificateFactory, StructuredFields.parseItem(clientCert)));
+            for (String value : httpFields.getValuesList(CLIENT_CERT_CHAIN))
+            {
+                for (StructuredFields.Member member : StructuredFields.parseList(value))
+                {
+                    if (!(member instanceof StructuredFields.Item))
+                        throw new IllegalArgumentException("Invalid " + CLIENT_CERT_CHAIN);
+                    certs.add(toCertificate(certificateFactory, (StructuredFields.Item)member));
+                }
+            }
+            request.setAttributes(new ClientCertAttributes(request.getAttributes(), certs.toArray(new X509Certificate[0])));
+        }
+        catch (CertificateException | IllegalArgumentException | ClassCastException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid {} headers", CLIENT_CERT, x);
+            throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Invalid " + CLIENT_CERT);
+        }
+    }
+
+    private X509Certificate toCertificate(CertificateFactory certificateFactory, StructuredFields.Item item) throws CertificateException
+    {
+        return (X509Certificate)certificateFactory.generateCertificate(new ByteArrayInputStream(item.asBytes()));
+    }
+
+    /**
+     * @param request the request
+     * @return whether the request was received directly from a trusted proxy
+     */
+    protected boolean isTrustedProxy(Request request)
+    {
+        if (trustedProxies.isEmpty())
+            return false;
+        EndPoint endPoint = request.getHttpChannel().getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+            endPoint = ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getEndPoint();
+        // The PROXY protocol replaces the remote address with the one of the client.
+        if (endPoint instanceof ProxyConnectionFactory.ProxyEndPoint)
+            endPoint = ((ProxyConnectionFactory.ProxyEndPoint)endPoint).unwrap();
+        SocketAddress remote = endPoint.getRemoteSocketAddress();
+        if (!(remote instanceof InetSocketAddress))
+            return false;
+        return trustedProxies.test(((InetSocketAddress)remote).getAddress());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), trustedProxies);
+    }
+
+    private static class ClientCertAttributes extends Attributes.Wrapper
+    {
+        private final X509Certificate[] _certs;
+
+        private ClientCertAttributes(Attributes attributes, X509Certificate[] certs)
+        {
+            super(attributes);
+            _certs = certs;
+        }
+
+        @Override
+        public Object getAttribute(String name)
+        {
+            if (SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_X_509_CERTIFICATE.equals(name))
+                return _certs;
+            return super.getAttribute(name);
+        }
+
+        @Override
+        public Set<String> getAttributeNameSet()
+        {
+            Set<String> names = new HashSet<>(_attributes.getAttributeNameSet());
+            names.add(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_X_509_CERTIFICATE);
+            return names;
+        }
+    }
+}
@@ -16,7 +16,15 @@ package org.eclipse.jetty.server;
 import java.lang.invoke.MethodHandle;
 import java.lang.invoke.MethodHandles;
 import java.lang.invoke.MethodType;
+import java.net.InetAddress;
 import java.net.InetSocketAddress;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.regex.Pattern;
 import javax.servlet.ServletRequest;
 
 import org.eclipse.jetty.http.BadMessageException;
@@ -30,6 +38,8 @@ import org.eclipse.jetty.http.QuotedCSVParser;
 import org.eclipse.jetty.server.HttpConfiguration.Customizer;
 import org.eclipse.jetty.util.HostPort;
 import org.eclipse.jetty.util.Index;
+import org.eclipse.jetty.util.InetAddressSet;
+import org.eclipse.jetty.util.QuotedStringTokenizer;
 import org.eclipse.jetty.util.StringUtil;
 
 import static java.lang.invoke.MethodType.methodType;
@@ -134,12 +144,37 @@ import static java.lang.invoke.MethodType.methodType;
  *         </tr>
  *     </tbody>
  * </table>
+ * <p>The complete list of {@code Forwarded} elements is parsed, in order from the
+ * client to the last proxy, and exposed as a {@code List} of {@link Hop}s under the
+ * {@link #FORWARDED_CHAIN_ATTRIBUTE} request attribute, while the client address
+ * determined from the {@code Forwarded} or {@code X-Forwarded-For} headers is exposed
+ * under the {@link #CLIENT_ADDRESS_ATTRIBUTE} request attribute.
+ * Obfuscated identifiers and ports, such as {@code for=_hidden} or
+ * {@code for="192.0.2.43:_port"}, are reported in the chain but never used as
+ * the request remote address or port.</p>
+ * <p>By default every peer is trusted to send forwarded headers, and the left-most
+ * values are used. If {@link #setTrustedProxies(String...) trusted proxies} are configured,
+ * the forwarded headers are ignored unless the connection peer is a trusted proxy, and the
+ * proxy chain is verified from right to left: a hop is only trusted if it has been added
+ * by a trusted proxy, so that the client address is the right-most address that is not a
+ * trusted proxy, and the {@code host}, {@code proto} and {@code by} parameters are only
+ * taken from trusted hops.</p>
  *
  * @see <a href="http://en.wikipedia.org/wiki/X-Forwarded-For">Wikipedia: X-Forwarded-For</a>
  * @see <a href="https://tools.ietf.org/html/rfc7239">RFC 7239: Forwarded HTTP Extension</a>
  */
 public class ForwardedRequestCustomizer implements Customizer
 {
+    /**
+     * The name of the request attribute holding the {@code List} of {@link Hop}s of the {@code Forwarded} header.
+     */
+    public static final String FORWARDED_CHAIN_ATTRIBUTE = "org.eclipse.jetty.server.forwarded.chain";
+    /**
+     * The name of the request attribute holding the client address, as a {@code String}, found in the forwarded headers.
+     */
+    public static final String CLIENT_ADDRESS_ATTRIBUTE = "org.eclipse.jetty.server.forwarded.clientAddress";
+    private static final Pattern IPV4 = Pattern.compile("\\d{1,3}(\\.\\d{1,3}){3}");
+
     private HostPortHttpField _forcedHost;
     private boolean _proxyAsAuthority = false;
     private boolean _forwardedPortAsAuthority = true;
@@ -153,6 +188,7 @@ public class ForwardedRequestCustomizer implements Customizer
     private String _forwardedCipherSuiteHeader = "Proxy-auth-cert";
     private String _forwardedSslSessionIdHeader = "Proxy-ssl-id";
     private boolean _sslIsSecure = true;
+    private final InetAddressSet _trustedProxies = new InetAddressSet();
     private final Index.Mutable<MethodHandle> _handles = new Index.Builder<MethodHandle>()
         .caseSensitive(false)
         .mutable()
@@ -451,13 +487,119 @@ public class ForwardedRequestCustomizer implements Customizer
         _sslIsSecure = sslIsSecure;
     }
 
+    /**
+     * @return the patterns of the trusted proxy addresses, or an empty set if every peer is trusted
+     */
+    public Set<String> getTrustedProxies()
+    {
+        return Collections.unmodifiableSet(_trustedProxies);
+    }
+
+    /**
+     * <p>Sets the trusted proxies, replacing the existing ones.</p>
+     *
+     * @param patterns the patterns of the trusted proxy addresses, such as
+     * {@code 10.0.0.0/8}, {@code 192.168.1.1-192.168.1.9} or {@code fd00::/8}
+     * @see InetAddressSet
+     */
+    public void setTrustedProxies(String... patterns)
+    {
+        _trustedProxies.clear();
+        for (String pattern : patterns)
+        {
+            addTrustedProxy(pattern);
+        }
+    }
+
+    /**
+     * @param pattern the pattern of trusted proxy addresses
+     * @see #setTrustedProxies(String...)
+     */
+    public void addTrustedProxy(String pattern)
+    {
+        if (StringUtil.isNotBlank(pattern))
+            _trustedProxies.add(pattern.trim());
+    }
+
+    /**
+     * @param request the request
+     * @return whether the connection peer is trusted to send forwarded headers
+     */
+    protected boolean isTrustedPeer(Request request)
+    {
+        if (_trustedProxies.isEmpty())
+            return true;
+        InetSocketAddress remote = request.getRemoteInetSocketAddress();
+        return remote != null && _trustedProxies.test(remote.getAddress());
+    }
+
+    /**
+     * @param node a node identifier found in the forwarded headers
+     * @return whether the node is the address of a trusted proxy; obfuscated
+     * identifiers and host names are never trusted
+     */
+    protected boolean isTrustedProxy(String node)
+    {
+        if (_trustedProxies.isEmpty())
+            return true;
+        HostPort hostPort = toHostPort(node);
+        if (hostPort == null)
+            return false;
+        String host = hostPort.getHost();
+        if (host.startsWith("["))
+            host = host.substring(1, host.length() - 1);
+        else if (!IPV4.matcher(host).matches())
+            return false;
+        try
+        {
+            // Only IP literals get here, so there is no lookup.
+            return _trustedProxies.test(InetAddress.getByName(host));
+        }
+        catch (Throwable x)
+        {
+            return false;
+        }
+    }
+
+    /**
+     * @param node a node identifier, such as {@code 192.0.2.43:8080}, {@code "[2001:db8::1]:_port"} or {@code _hidden}
+     * @return the host and port of the node, without the port if it is obfuscated,
+     * or null if the node is unknown or obfuscated
+     */
+    private static HostPort toHostPort(String node)
+    {
+        if (StringUtil.isBlank(node) || node.startsWith("_") || "unknown".equalsIgnoreCase(node))
+            return null;
+        int close = node.lastIndexOf(']');
+        int colon = node.lastIndexOf(':');
+        boolean hasPort = close >= 0 ? colon > close : colon >= 0 && colon == node.indexOf(':');
+        if (hasPort && node.startsWith("_", colon + 1))
+            return new HostPort(node.substring(0, colon), 0);
+        return new HostPort(node);
+    }
+
     @Override
     public void customize(Connector connector, HttpConfiguration config, Request request)
     {
         HttpFields httpFields = request.getHttpFields();
 
-        // Do a single pass through the header fields as it is a more efficient single iteration.
         Forwarded forwarded = new Forwarded(request, config);
+        if (!isTrustedPeer(request))
+        {
+            // The headers of an untrusted peer are only reported.
+            if (StringUtil.isNotBlank(getForwardedHeader()))
+            {
+                for (HttpField field : httpFields.getFields(getForwardedHeader()))
+                {
+                    forwarded.handleRFC7239(field);
+                }
+            }
+            if (!forwarded._chain.isEmpty())
+                request.setAttribute(FORWARDED_CHAIN_ATTRIBUTE, Collections.unmodifiableList(forwarded._chain));
+            return;
+        }
+
+        // Do a single pass through the header fields as it is a more efficient single iteration.
         boolean match = false;
         for (HttpField field : httpFields)
         {
@@ -478,6 +620,12 @@ public class ForwardedRequestCustomizer implements Customizer
 
         if (match)
         {
+            forwarded.resolve();
+            if (!forwarded._chain.isEmpty())
+                request.setAttribute(FORWARDED_CHAIN_ATTRIBUTE, Collections.unmodifiableList(forwarded._chain));
+            if (forwarded._clientAddress != null)
+                request.setAttribute(CLIENT_ADDRESS_ATTRIBUTE, forwarded._clientAddress);
+
             HttpURI.Mutable builder = HttpURI.build(request.getHttpURI());
             boolean httpUriChanged = false;
 
@@ -719,6 +867,93 @@ public class ForwardedRequestCustomizer implements Customizer
         }
     }
 
+    /**
+     * <p>An element of the {@code Forwarded} header, describing one proxy hop.</p>
+     * <p>The parameter values are reported as they were received, so that
+     * node identifiers may be obfuscated, such as {@code _hidden}, or {@code unknown}.</p>
+     */
+    public static class Hop
+    {
+        private final Map<String, String> _params;
+        private boolean _verified;
+
+        private Hop(Map<String, String> params)
+        {
+            _params = Collections.unmodifiableMap(params);
+        }
+
+        /**
+         * @return the {@code for} parameter, identifying the node that sent the request to the proxy
+         */
+        public String getFor()
+        {
+            return _params.get("for");
+        }
+
+        /**
+         * @return the {@code by} parameter, identifying the interface of the proxy that received the request
+         */
+        public String getBy()
+        {
+            return _params.get("by");
+        }
+
+        /**
+         * @return the {@code host} parameter, the {@code Host} header of the request received by the proxy
+         */
+        public String getHost()
+        {
+            return _params.get("host");
+        }
+
+        /**
+         * @return the {@code proto} parameter, the scheme of the request received by the proxy
+         */
+        public String getProto()
+        {
+            return _params.get("proto");
+        }
+
+        /**
+         * @param name the lower case parameter name
+         * @return the parameter value, or null if the parameter is not present
+         */
+        public String getParameter(String name)
+        {
+            return _params.get(name);
+        }
+
+        /**
+         * @return all the parameters of this hop, including extensions, by lower case name
+         */
+        public Map<String, String> getParameters()
+        {
+            return _params;
+        }
+
+        /**
+         * @return whether this hop has been added by a trusted proxy
+         * @see #setTrustedProxies(String...)
+         */
+        public boolean isVerified()
+        {
+            return _verified;
+        }
+
+        @Override
+        public String toString()
+        {
+            StringBuilder builder = new StringBuilder();
+            for (Map.Entry<String, String> param : _params.entrySet())
+            {
+                if (builder.length() > 0)
+                    builder.append(';');
+                builder.append(param.getKey()).append('=').append(QuotedStringTokenizer.quoteIfNeeded(param.getValue(), "\"\\ \t;,:[]="));
+            }
+            return builder.toString();
+        }
+    }
+
     private class Forwarded extends QuotedCSVParser
     {
         HttpConfiguration _config;
@@ -730,6 +965,11 @@ public class ForwardedRequestCustomizer implements Customizer
         Source _protoSource = Source.UNSET;
         Boolean _secure;
         boolean _secureScheme = false;
+        final List<Hop> _chain = new ArrayList<>();
+        final List<String> _forwardedFor = new ArrayList<>();
+        final List<HttpField> _forwardedForFields = new ArrayList<>();
+        Map<String, String> _params;
+        String _clientAddress;
 
         public Forwarded(Request request, HttpConfiguration config)
         {
@@ -818,8 +1058,14 @@ public class ForwardedRequestCustomizer implements Customizer
          */
         public void handleForwardedFor(HttpField field)
         {
-            HostPort hostField = new HostPort(getLeftMost(field.getValue()));
-            getFor().setHostPort(hostField, Source.XFORWARDED_FOR);
+            for (String value : field.getValues())
+            {
+                if (StringUtil.isNotBlank(value))
+                {
+                    _forwardedFor.add(value.trim());
+                    _forwardedForFields.add(field);
+                }
+            }
         }
 
         /**
@@ -888,41 +1134,127 @@ public class ForwardedRequestCustomizer implements Customizer
             {
                 String name = StringUtil.asciiToLowerCase(buffer.substring(paramName, paramValue - 1));
                 String value = buffer.substring(paramValue);
-                switch (name)
+                if (_params == null)
+                    _params = new LinkedHashMap<>();
+                _params.putIfAbsent(name, value);
+            }
+        }
+
+        @Override
+        protected void parsedValueAndParams(StringBuffer buffer)
+        {
+            if (_params != null)
+            {
+                // Invalid nodes are reported while the header is parsed.
+                toHostPort(_params.get("for"));
+                toHostPort(_params.get("host"));
+                if (getProxyAsAuthority())
+                    toHostPort(_params.get("by"));
+                _chain.add(new Hop(_params));
+            }
+            _params = null;
+        }
+
+        /**
+         * Applies the hops of the {@code Forwarded} chain and the {@code X-Forwarded-For}
+         * addresses, after all the headers have been seen.
+         */
+        private void resolve()
+        {
+            boolean verify = !_trustedProxies.isEmpty();
+
+            if (!_chain.isEmpty())
+            {
+                // The last hop has been added by the trusted peer, each previous
+                // hop is trusted only if it was added by a trusted proxy.
+                int first = 0;
+                if (verify)
                 {
-                    case "by":
+                    first = _chain.size() - 1;
+                    while (first > 0 && isTrustedProxy(_chain.get(first).getFor()))
                     {
-                        if (!getProxyAsAuthority())
-                            break;
-                        if (value.startsWith("_") || "unknown".equals(value))
-                            break;
-                        HostPort hostField = new HostPort(value);
-                        getAuthority().setHostPort(hostField.getHost(), hostField.getPort(), Source.FORWARDED);
-                        break;
+                        --first;
                     }
-                    case "for":
+                    _clientAddress = _chain.get(first).getFor();
+                }
+
+                // The left-most parameters of the trusted hops win.
+                for (int i = first; i < _chain.size(); ++i)
+                {
+                    Hop hop = _chain.get(i);
+                    hop._verified = true;
+                    for (Map.Entry<String, String> param : hop.getParameters().entrySet())
                     {
-                        if (value.startsWith("_") || "unknown".equals(value))
-                            break;
-                        HostPort hostField = new HostPort(value);
-                        getFor().setHostPort(hostField.getHost(), hostField.getPort(), Source.FORWARDED);
-                        break;
+                        String value = param.getValue();
+                        switch (param.getKey())
+                        {
+                            case "by":
+                            {
+                                HostPort hostPort = getProxyAsAuthority() ? toHostPort(value) : null;
+                                if (hostPort != null)
+                                    getAuthority().setHostPort(hostPort.getHost(), hostPort.getPort(), Source.FORWARDED);
+                                break;
+                            }
+                            case "for":
+                            {
+                                // When verifying, only the left-most trusted hop identifies the client.
+                                HostPort hostPort = i == first || !verify ? toHostPort(value) : null;
+                                if (hostPort != null && !hasFor(Source.FORWARDED))
+                                {
+                                    getFor().setHostPort(hostPort.getHost(), hostPort.getPort(), Source.FORWARDED);
+                                    if (_clientAddress == null)
+                                        _clientAddress = value;
+                                }
+                                break;
+                            }
+                            case "host":
+                            {
+                                HostPort hostPort = toHostPort(value);
+                                if (hostPort != null)
+                                    getAuthority().setHostPort(hostPort.getHost(), hostPort.getPort(), Source.FORWARDED);
+                                break;
+                            }
+                            case "proto":
+                                updateProto(value, Source.FORWARDED);
+                                break;
+                            default:
+                                break;
+                        }
                     }
-                    case "host":
+                }
+            }
+
+            if (!_forwardedFor.isEmpty())
+            {
+                int index = 0;
+                if (verify)
+                {
+                    index = _forwardedFor.size() - 1;
+                    while (index > 0 && isTrustedProxy(_forwardedFor.get(index)))
                     {
-                        if (value.startsWith("_") || "unknown".equals(value))
-                            break;
-                        HostPort hostField = new HostPort(value);
-                        getAuthority().setHostPort(hostField.getHost(), hostField.getPort(), Source.FORWARDED);
-                        break;
+                        --index;
                     }
-                    case "proto":
-                        updateProto(value, Source.FORWARDED);
-                        break;
                 }
+                String address = _forwardedFor.get(index);
+                try
+                {
+                    getFor().setHostPort(new HostPort(address), Source.XFORWARDED_FOR);
+                }
+                catch (Throwable x)
+                {
+                    onError(_forwardedForFields.get(index), x);
+                    return;
+                }
+                if (_clientAddress == null)
+                    _clientAddress = address;
             }
         }
 
+        private boolean hasFor(Source source)
+        {
+            return _for != null && _for._hostSource == source;
+        }
+
         private void updateAuthority(String value, Source source)
         {
             HostPort hostField = new HostPort(value);
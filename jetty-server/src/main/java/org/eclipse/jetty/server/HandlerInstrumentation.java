@@ -0,0 +1,113 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+/**
+ * <p>Instrumentation of the invocations of the {@link Handler}s of a {@link Server}.</p>
+ * <p>The instrumentation set with {@link Server#setHandlerInstrumentation(HandlerInstrumentation)}
+ * is notified when a handler container invokes one of its child handlers, including the
+ * scoped invocations of {@link org.eclipse.jetty.server.handler.ScopedHandler}s and the
+ * asynchronous I/O callbacks run in the scope of a context, and when the request completes.</p>
+ * <p>Invocations are nested: the invocation of a handler contains the invocations of the
+ * handlers that it invokes, and begin and end events are always notified by the same thread.</p>
+ * <p>Methods are invoked for every handler of every request, so implementations must be
+ * thread-safe, fast and must not throw.</p>
+ *
+ * @see HandlerProfiler
+ */
+public interface HandlerInstrumentation
+{
+    /**
+     * <p>Invoked before the given handler is invoked.</p>
+     *
+     * @param handler the handler about to be invoked
+     * @param request the request being handled
+     * @return an opaque context passed to {@link #onHandleEnd(Handler, Request, Object, Throwable)}
+     */
+    Object onHandleBegin(Handler handler, Request request);
+
+    /**
+     * <p>Invoked after the given handler returned or threw.</p>
+     *
+     * @param handler the handler that was invoked
+     * @param request the request being handled
+     * @param context the context returned by {@link #onHandleBegin(Handler, Request)}
+     * @param failure the failure thrown by the handler, or null if the handler returned
+     */
+    void onHandleEnd(Handler handler, Request request, Object context, Throwable failure);
+
+    /**
+     * <p>Invoked when the request and response processing is complete.</p>
+     *
+     * @param request the request that completed
+     */
+    default void onComplete(Request request)
+    {
+    }
+
+    /**
+     * <p>Invokes {@link Handler#handle(String, Request, HttpServletRequest, HttpServletResponse)}
+     * on the given handler, notifying the instrumentation of the server, if any.</p>
+     * <p>Handler containers should use this method to invoke their child handlers.</p>
+     *
+     * @param handler the handler to invoke
+     * @param target the target of the request
+     * @param baseRequest the original unwrapped request
+     * @param request the request, possibly wrapped
+     * @param response the response, possibly wrapped
+     * @throws IOException if thrown by the handler
+     * @throws ServletException if thrown by the handler
+     */
+    static void handle(Handler handler, String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HandlerInstrumentation instrumentation = from(baseRequest);
+        if (instrumentation == null)
+        {
+            handler.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        Object context = instrumentation.onHandleBegin(handler, baseRequest);
+        Throwable failure = null;
+        try
+        {
+            handler.handle(target, baseRequest, request, response);
+        }
+        catch (Throwable x)
+        {
+            failure = x;
+            throw x;
+        }
+        finally
+        {
+            instrumentation.onHandleEnd(handler, baseRequest, context, failure);
+        }
+    }
+
+    /**
+     * @param request the request being handled
+     * @return the instrumentation of the server handling the request, or null
+     */
+    static HandlerInstrumentation from(Request request)
+    {
+        HttpChannel channel = request == null ? null : request.getHttpChannel();
+        Server server = channel == null ? null : channel.getServer();
+        return server == null ? null : server.getHandlerInstrumentation();
+    }
+}
@@ -0,0 +1,341 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.lang.management.ManagementFactory;
+import java.lang.management.ThreadMXBean;
+import java.util.Comparator;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerInstrumentation} that records, for each handler, the CPU time
+ * used and the bytes allocated by the threads invoking it.</p>
+ * <p>The CPU time and the allocated bytes are measured with the {@link ThreadMXBean} of the JVM,
+ * both including the nested handler invocations, and excluding them (the <em>self</em> values),
+ * so that the self values identify which handler of the chain consumes resources.
+ * A handler that delegates to a child handler without using
+ * {@link HandlerInstrumentation#handle(Handler, String, Request, javax.servlet.http.HttpServletRequest, javax.servlet.http.HttpServletResponse)}
+ * is charged with the resources of the child.</p>
+ * <p>Allocated bytes are only recorded if the JVM supports
+ * {@code com.sun.management.ThreadMXBean#getThreadAllocatedBytes(long)}.</p>
+ * <p>Usage:</p>
+ * <pre>
+ * Server server = new Server();
+ * server.setHandlerInstrumentation(new HandlerProfiler());
+ * </pre>
+ *
+ * @see org.eclipse.jetty.server.handler.StatisticsHandler#getHandlerProfiles()
+ */
+@ManagedObject("CPU time and allocated bytes per handler")
+public class HandlerProfiler implements HandlerInstrumentation, Dumpable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HandlerProfiler.class);
+
+    private final ConcurrentMap<Handler, Profile> _profiles = new ConcurrentHashMap<>();
+    private final ThreadLocal<Frame> _frames = new ThreadLocal<>();
+    private final ThreadMXBean _threadMXBean;
+    private final boolean _cpuTimeSupported;
+    private final boolean _allocationSupported;
+
+    public HandlerProfiler()
+    {
+        _threadMXBean = ManagementFactory.getThreadMXBean();
+        _cpuTimeSupported = _threadMXBean.isCurrentThreadCpuTimeSupported() && _threadMXBean.isThreadCpuTimeEnabled();
+        _allocationSupported = Allocations.isSupported(_threadMXBean);
+        if (LOG.isDebugEnabled())
+            LOG.debug("CPU time supported={}, allocation supported={}", _cpuTimeSupported, _allocationSupported);
+    }
+
+    /**
+     * @return whether the CPU time of the handlers is recorded
+     */
+    @ManagedAttribute("whether the CPU time is recorded")
+    public boolean isCpuTimeSupported()
+    {
+        return _cpuTimeSupported;
+    }
+
+    /**
+     * @return whether the bytes allocated by the handlers are recorded
+     */
+    @ManagedAttribute("whether the allocated bytes are recorded")
+    public boolean isAllocationSupported()
+    {
+        return _allocationSupported;
+    }
+
+    @Override
+    public Object onHandleBegin(Handler handler, Request request)
+    {
+        Frame frame = new Frame(_frames.get(), cpuTime(), allocatedBytes());
+        _frames.set(frame);
+        return frame;
+    }
+
+    @Override
+    public void onHandleEnd(Handler handler, Request request, Object context, Throwable failure)
+    {
+        if (!(context instanceof Frame))
+            return;
+        Frame frame = (Frame)context;
+        long cpuTime = cpuTime() - frame._cpuTime;
+        long allocated = allocatedBytes() - frame._allocated;
+        Frame parent = frame._parent;
+        if (parent != null)
+        {
+            parent._childCpuTime += cpuTime;
+            parent._childAllocated += allocated;
+        }
+        _frames.set(parent);
+        _profiles.computeIfAbsent(handler, Profile::new)
+            .record(cpuTime, cpuTime - frame._childCpuTime, allocated, allocated - frame._childAllocated, failure != null);
+    }
+
+    private long cpuTime()
+    {
+        return _cpuTimeSupported ? _threadMXBean.getCurrentThreadCpuTime() : 0;
+    }
+
+    private long allocatedBytes()
+    {
+        return _allocationSupported ? Allocations.getCurrentThreadAllocatedBytes(_threadMXBean) : 0;
+    }
+
+    /**
+     * @param handler the handler
+     * @return the profile of the given handler, or null if it has not been invoked
+     */
+    public Profile getProfile(Handler handler)
+    {
+        return _profiles.get(handler);
+    }
+
+    /**
+     * @return the profiles of the handlers, by decreasing self CPU time
+     */
+    public List<Profile> getProfiles()
+    {
+        return _profiles.values().stream()
+            .sorted(Comparator.comparingLong(Profile::getSelfCpuTime).reversed())
+            .collect(Collectors.toList());
+    }
+
+    /**
+     * @return a description of the profiles, keyed by handler, by decreasing self CPU time
+     */
+    @ManagedAttribute("profile per handler")
+    public Map<String, String> getProfileDescriptions()
+    {
+        Map<String, String> result = new LinkedHashMap<>();
+        getProfiles().forEach(profile -> result.put(profile.getName(), profile.toSummary()));
+        return result;
+    }
+
+    /**
+     * <p>Discards the profiles of all the handlers.</p>
+     */
+    @ManagedOperation(value = "resets the profiles", impact = "ACTION")
+    public void reset()
+    {
+        _profiles.clear();
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        Dumpable.dumpObjects(out, indent, this, getProfiles().toArray());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{cpu=%b,allocation=%b,handlers=%d}", getClass().getSimpleName(), hashCode(), _cpuTimeSupported, _allocationSupported, _profiles.size());
+    }
+
+    private static class Frame
+    {
+        private final Frame _parent;
+        private final long _cpuTime;
+        private final long _allocated;
+        private long _childCpuTime;
+        private long _childAllocated;
+
+        private Frame(Frame parent, long cpuTime, long allocated)
+        {
+            _parent = parent;
+            _cpuTime = cpuTime;
+            _allocated = allocated;
+        }
+    }
+
+    /**
+     * <p>The CPU time and allocated bytes of a handler.</p>
+     */
+    public static class Profile
+    {
+        private final LongAdder _invocations = new LongAdder();
+        private final LongAdder _failures = new LongAdder();
+        private final LongAdder _cpuTime = new LongAdder();
+        private final LongAdder _selfCpuTime = new LongAdder();
+        private final LongAdder _allocated = new LongAdder();
+        private final LongAdder _selfAllocated = new LongAdder();
+        private final Handler _handler;
+        private final String _name;
+
+        private Profile(Handler handler)
+        {
+            _handler = handler;
+            if (handler instanceof ContextHandler)
+                _name = String.format("%s@%x{%s}", handler.getClass().getSimpleName(), handler.hashCode(), ((ContextHandler)handler).getContextPath());
+            else
+                _name = String.format("%s@%x", handler.getClass().getSimpleName(), handler.hashCode());
+        }
+
+        private void record(long cpuTime, long selfCpuTime, long allocated, long selfAllocated, boolean failed)
+        {
+            _invocations.increment();
+            if (failed)
+                _failures.increment();
+            _cpuTime.add(cpuTime);
+            _selfCpuTime.add(selfCpuTime);
+            _allocated.add(allocated);
+            _selfAllocated.add(selfAllocated);
+        }
+
+        /**
+         * @return the profiled handler
+         */
+        public Handler getHandler()
+        {
+            return _handler;
+        }
+
+        /**
+         * @return the name of the profiled handler
+         */
+        public String getName()
+        {
+            return _name;
+        }
+
+        /**
+         * @return the number of invocations of the handler, counting the scope and the handle
+         * phases of {@link org.eclipse.jetty.server.handler.ScopedHandler}s separately
+         */
+        public long getInvocations()
+        {
+            return _invocations.longValue();
+        }
+
+        /**
+         * @return the number of invocations that threw
+         */
+        public long getFailures()
+        {
+            return _failures.longValue();
+        }
+
+        /**
+         * @return the CPU time (in nanoseconds) of the invocations, including nested handlers
+         */
+        public long getCpuTime()
+        {
+            return _cpuTime.longValue();
+        }
+
+        /**
+         * @return the CPU time (in nanoseconds) of the invocations, excluding nested handlers
+         */
+        public long getSelfCpuTime()
+        {
+            return _selfCpuTime.longValue();
+        }
+
+        /**
+         * @return the bytes allocated by the invocations, including nested handlers
+         */
+        public long getAllocatedBytes()
+        {
+            return _allocated.longValue();
+        }
+
+        /**
+         * @return the bytes allocated by the invocations, excluding nested handlers
+         */
+        public long getSelfAllocatedBytes()
+        {
+            return _selfAllocated.longValue();
+        }
+
+        /**
+         * @return a summary of the profile
+         */
+        public String toSummary()
+        {
+            return String.format("invocations=%d,failures=%d,cpu=%dus,selfCpu=%dus,allocated=%dB,selfAllocated=%dB",
+                getInvocations(), getFailures(),
+                getCpuTime() / 1000, getSelfCpuTime() / 1000,
+                getAllocatedBytes(), getSelfAllocatedBytes());
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s{%s}", _name, toSummary());
+        }
+    }
+
+    /**
+     * <p>Isolates the references to {@code com.sun.management}, that may not be available.</p>
+     */
+    private static class Allocations
+    {
+        private static boolean isSupported(ThreadMXBean threadMXBean)
+        {
+            try
+            {
+                if (!(threadMXBean instanceof com.sun.management.ThreadMXBean))
+                    return false;
+                com.sun.management.ThreadMXBean bean = (com.sun.management.ThreadMXBean)threadMXBean;
+                return bean.isThreadAllocatedMemorySupported() && bean.isThreadAllocatedMemoryEnabled();
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Thread allocated memory not available", x);
+                return false;
+            }
+        }
+
+        private static long getCurrentThreadAllocatedBytes(ThreadMXBean threadMXBean)
+        {
+            return ((com.sun.management.ThreadMXBean)threadMXBean).getThreadAllocatedBytes(Thread.currentThread().getId());
+        }
+    }
+}
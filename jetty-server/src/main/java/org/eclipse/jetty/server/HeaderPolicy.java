@@ -0,0 +1,87 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+
+/**
+ * <p>A policy applied to the request header fields while they are parsed,
+ * before the request is created and reaches the handlers.</p>
+ * <p>A policy is configured per connector with
+ * {@link HttpConfiguration#setHeaderPolicy(HeaderPolicy)}, and is applied to
+ * HTTP/1.1 and HTTP/2 requests, so that it can normalize, drop or reject
+ * header fields; for example to strip internal headers arriving from the
+ * internet, or to reject requests with an excessive number of header fields.</p>
+ * <p>The {@code Content-Length}, {@code Transfer-Encoding} and {@code Host}
+ * header fields determine the framing of the request content and the request
+ * authority, which are decided by the parser before the policy is applied:
+ * a policy may reject requests because of these fields, but any attempt to
+ * normalize or drop them is ignored.</p>
+ * <p>Implementations must be thread-safe and should be fast, as they are
+ * invoked for every header field of every request.</p>
+ *
+ * @see RuleBasedHeaderPolicy
+ */
+public interface HeaderPolicy
+{
+    /**
+     * <p>Applies this policy to a request header field, as soon as it is parsed.</p>
+     *
+     * @param field the parsed request header field
+     * @return the field to add to the request, possibly normalized, or null to drop the field
+     * @throws BadMessageException to reject the request
+     */
+    HttpField onHeader(HttpField field) throws BadMessageException;
+
+    /**
+     * <p>Checks all the request header fields, after they have been parsed
+     * and {@link #onHeader(HttpField) processed} one by one.</p>
+     *
+     * @param fields the request header fields
+     * @throws BadMessageException to reject the request
+     */
+    default void onHeaders(HttpFields fields) throws BadMessageException
+    {
+    }
+
+    /**
+     * <p>Applies the given policy to a request header field, keeping the fields
+     * that determine the framing of the request unchanged.</p>
+     *
+     * @param policy the policy to apply
+     * @param field the parsed request header field
+     * @return the field to add to the request, or null to drop the field
+     * @throws BadMessageException to reject the request
+     * @see #isFramingField(HttpField)
+     */
+    static HttpField apply(HeaderPolicy policy, HttpField field) throws BadMessageException
+    {
+        HttpField result = policy.onHeader(field);
+        return isFramingField(field) ? field : result;
+    }
+
+    /**
+     * @param field the request header field
+     * @return whether the field determines the framing of the request content
+     * or the request authority, and therefore cannot be normalized or dropped
+     */
+    static boolean isFramingField(HttpField field)
+    {
+        HttpHeader header = field.getHeader();
+        return header == HttpHeader.CONTENT_LENGTH || header == HttpHeader.TRANSFER_ENCODING || header == HttpHeader.HOST;
+    }
+}
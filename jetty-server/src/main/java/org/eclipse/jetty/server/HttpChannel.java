@@ -17,6 +17,7 @@ import java.io.IOException;
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
 import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
 import java.util.ArrayList;
 import java.util.EventListener;
 import java.util.List;
@@ -34,6 +35,7 @@ import javax.servlet.RequestDispatcher;
 import javax.servlet.ServletException;
 
 import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.CapsuleConnection;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpGenerator;
@@ -91,6 +93,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
     private MetaData.Response _committedMetaData;
     private RequestLog _requestLog;
     private long _oldIdleTimeout;
+    private Executor _dispatchExecutor;
 
     /**
      * Bytes written after interception (eg after compression)
@@ -228,6 +231,15 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         return _written;
     }
 
+    /**
+     * @return the id of the stream that carries the request, for protocols that
+     * multiplex requests over streams such as HTTP/2 and HTTP/3, or -1
+     */
+    public long getStreamId()
+    {
+        return -1;
+    }
+
     /**
      * @return the number of requests handled by this connection
      */
@@ -452,6 +464,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         _requestLog = _connector == null ? null : _connector.getServer().getRequestLog();
         _written = 0;
         _oldIdleTimeout = 0;
+        _dispatchExecutor = null;
         _transientListeners.clear();
     }
 
@@ -584,7 +597,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
                     {
                         ContextHandler handler = _state.getContextHandler();
                         if (handler != null)
-                            handler.handle(_request, _request.getHttpInput());
+                            runCallback(handler, _request.getHttpInput());
                         else
                             _request.getHttpInput().run();
                         break;
@@ -594,7 +607,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
                     {
                         ContextHandler handler = _state.getContextHandler();
                         if (handler != null)
-                            handler.handle(_request, _response.getHttpOutput());
+                            runCallback(handler, _response.getHttpOutput());
                         else
                             _response.getHttpOutput().run();
                         break;
@@ -764,6 +777,32 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         }
     }
 
+    private void runCallback(ContextHandler handler, Runnable callback)
+    {
+        HandlerInstrumentation instrumentation = getServer().getHandlerInstrumentation();
+        if (instrumentation == null)
+        {
+            handler.handle(_request, callback);
+            return;
+        }
+
+        Object context = instrumentation.onHandleBegin(handler, _request);
+        Throwable failure = null;
+        try
+        {
+            handler.handle(_request, callback);
+        }
+        catch (Throwable x)
+        {
+            failure = x;
+            throw x;
+        }
+        finally
+        {
+            instrumentation.onHandleEnd(handler, _request, context, failure);
+        }
+    }
+
     /**
      * <p>Sends an error 500, performing a special logic to detect whether the request is suspended,
      * to avoid concurrent writes from the application.</p>
@@ -927,6 +966,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         if (LOG.isDebugEnabled())
             LOG.debug("onRequestComplete {}", this);
         boolean result = eof();
+        _request.onContentComplete(null);
         _combinedListener.onRequestEnd(_request);
         return result;
     }
@@ -956,6 +996,9 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
 
         _request.onCompleted();
         _combinedListener.onComplete(_request);
+        HandlerInstrumentation instrumentation = getServer().getHandlerInstrumentation();
+        if (instrumentation != null)
+            instrumentation.onComplete(_request);
         _transport.onCompleted();
     }
 
@@ -967,6 +1010,7 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
             failure = new BadMessageException(HttpStatus.BAD_REQUEST_400, reason, failure);
 
         _combinedListener.onRequestFailure(_request, failure);
+        _request.onContentComplete(failure);
 
         Action action;
         try
@@ -1123,6 +1167,45 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         sendResponse(null, content, complete, callback);
     }
 
+    /**
+     * @return whether the transport can send file content without copying it to user-space buffers
+     * @see HttpTransport#isSendFileSupported()
+     */
+    public boolean isSendFileSupported()
+    {
+        return _transport.isSendFileSupported();
+    }
+
+    /**
+     * <p>Non-Blocking write of a region of a file, without copying it to user-space buffers.</p>
+     * <p>The response must already be committed; the file content bypasses the
+     * {@link HttpOutput.Interceptor} chain and is not notified to
+     * {@link Listener#onResponseContent(Request, ByteBuffer)}.</p>
+     *
+     * @param file the file to send the content from
+     * @param position the position in the file of the first byte to send
+     * @param length the number of bytes to send
+     * @param callback Callback when complete or failed
+     * @see HttpTransport#sendFile(FileChannel, long, long, Callback)
+     */
+    public void sendFile(FileChannel file, long position, long length, Callback callback)
+    {
+        if (!isCommitted())
+        {
+            callback.failed(new IllegalStateException("not committed"));
+            return;
+        }
+        _transport.sendFile(file, position, length, new Callback.Nested(callback)
+        {
+            @Override
+            public void succeeded()
+            {
+                _written += length;
+                super.succeeded();
+            }
+        });
+    }
+
     @Override
     public void resetBuffer()
     {
@@ -1136,9 +1219,34 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         return null;
     }
 
+    /**
+     * @return the executor used to execute the async dispatches of the current request,
+     * or null if the server thread pool is used
+     */
+    public Executor getDispatchExecutor()
+    {
+        return _dispatchExecutor;
+    }
+
+    /**
+     * <p>Sets the executor used to execute the async dispatches of the current request,
+     * for example to handle the request in a virtual thread.</p>
+     * <p>The executor is reset when this channel is recycled.</p>
+     *
+     * @param executor the executor used to execute the async dispatches of the current request,
+     * or null to use the server thread pool
+     */
+    public void setDispatchExecutor(Executor executor)
+    {
+        _dispatchExecutor = executor;
+    }
+
     protected void execute(Runnable task)
     {
-        _executor.execute(task);
+        Executor executor = task == this ? _dispatchExecutor : null;
+        if (executor == null)
+            executor = _executor;
+        executor.execute(task);
     }
 
     public Scheduler getScheduler()
@@ -1183,6 +1291,30 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
         throw new UnsupportedOperationException("Tunnelling not supported");
     }
 
+    /**
+     * <p>Upgrades the tunnel of this CONNECT or Extended CONNECT request to a
+     * {@link CapsuleConnection}, so that capsules can be exchanged with the client
+     * as specified by <a href="https://datatracker.ietf.org/doc/html/rfc9297">RFC 9297</a>.</p>
+     * <p>The application must send a successful response, typically with the
+     * {@code Capsule-Protocol: ?1} header; the returned connection is installed on
+     * the tunnel when the response is complete, and only then the listener is notified
+     * and capsules can be sent.</p>
+     *
+     * @param listener the listener notified of the capsules sent by the client
+     * @param maxCapsuleLength the max capsule value length, in bytes
+     * @return the connection to send capsules to the client
+     * @throws UnsupportedOperationException if tunnelling is not supported
+     * @see #isTunnellingSupported()
+     */
+    public CapsuleConnection upgradeToCapsules(CapsuleConnection.Listener listener, int maxCapsuleLength)
+    {
+        CapsuleConnection connection = new CapsuleConnection(getTunnellingEndPoint(), _executor, getByteBufferPool(), listener, maxCapsuleLength);
+        _request.setAttribute(HttpTransport.UPGRADE_CONNECTION_ATTRIBUTE, connection);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Upgrading {} to {}", this, connection);
+        return connection;
+    }
+
     private void notifyEvent1(Function<Listener, Consumer<Request>> function, Request request)
     {
         for (Listener listener : _transientListeners)
@@ -1493,6 +1625,24 @@ public abstract class HttpChannel implements Runnable, HttpOutput.Interceptor
     @Deprecated
     public static class TransientListeners implements Listener
     {
+        /**
+         * <p>Adds a {@code TransientListeners} bean to the connectors of the given server
+         * that do not have one already, so that the listeners added with
+         * {@link HttpChannel#addListener(Listener)} are notified.</p>
+         *
+         * @param server the server whose connectors are configured, may be {@code null}
+         */
+        public static void addTo(Server server)
+        {
+            if (server == null)
+                return;
+            for (Connector connector : server.getConnectors())
+            {
+                if (connector.getBean(TransientListeners.class) == null)
+                    connector.addBean(new TransientListeners());
+            }
+        }
+
         @Override
         public void onRequestBegin(Request request)
         {
@@ -16,6 +16,7 @@ package org.eclipse.jetty.server;
 import java.io.IOException;
 import java.nio.ByteBuffer;
 import java.util.ArrayList;
+import java.util.Collection;
 import java.util.List;
 
 import org.eclipse.jetty.http.BadMessageException;
@@ -48,6 +49,7 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
     private static final HttpField PREAMBLE_UPGRADE_H2C = new HttpField(HttpHeader.UPGRADE, "h2c");
     private static final HttpInput.Content EOF = new HttpInput.EofContent();
     private final HttpConnection _httpConnection;
+    private final Collection<ComplianceViolation.Listener> _complianceListeners;
     private final RequestBuilder _requestBuilder = new RequestBuilder();
     private MetaData.Request _metadata;
     private HttpField _connection;
@@ -72,6 +74,7 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
     {
         super(connector, config, endPoint, transport);
         _httpConnection = httpConnection;
+        _complianceListeners = connector == null ? List.of() : connector.getBeans(ComplianceViolation.Listener.class);
     }
 
     @Override
@@ -340,6 +343,10 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
     @Override
     public boolean headerComplete()
     {
+        HeaderPolicy headerPolicy = getHttpConfiguration().getHeaderPolicy();
+        if (headerPolicy != null)
+            headerPolicy.onHeaders(_requestBuilder.getFields());
+
         _metadata = _requestBuilder.build();
         onRequest(_metadata);
 
@@ -349,6 +356,12 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
             _complianceViolations = null;
         }
 
+        if (_httpConnection.isPipelineLimitExceeded())
+        {
+            badMessage(new BadMessageException(HttpStatus.SERVICE_UNAVAILABLE_503, "Too many pipelined requests"));
+            return false;
+        }
+
         boolean persistent;
 
         switch (_metadata.getHttpVersion())
@@ -375,6 +388,8 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
                 else
                     persistent = false;
 
+                if (persistent && _httpConnection.isKeepAliveExhausted())
+                    persistent = false;
                 if (!persistent)
                     persistent = HttpMethod.CONNECT.is(_metadata.getMethod());
                 if (persistent)
@@ -406,6 +421,8 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
                 else
                     persistent = false;
 
+                if (persistent && _httpConnection.isKeepAliveExhausted())
+                    persistent = false;
                 if (!persistent)
                     persistent = HttpMethod.CONNECT.is(_metadata.getMethod());
                 if (!persistent)
@@ -441,6 +458,12 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
 
         if (!persistent)
             _httpConnection.getGenerator().setPersistent(false);
+        else if (getHttpConfiguration().isSendKeepAliveHeader() && !HttpMethod.CONNECT.is(_metadata.getMethod()))
+        {
+            String keepAlive = _httpConnection.getKeepAliveHeaderValue();
+            if (keepAlive != null)
+                getResponse().getHttpFields().add(HttpHeader.KEEP_ALIVE, keepAlive);
+        }
 
         // Should we delay dispatch until we have some content?
         // We should not delay if there is no content expect or client is expecting 100 or the response is already committed or the request buffer already has something in it to parse
@@ -485,6 +508,23 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
         return onRequestComplete();
     }
 
+    @Override
+    public void onComplianceViolation(ComplianceViolation.Event event)
+    {
+        ComplianceViolation.Listener.super.onComplianceViolation(event);
+        for (ComplianceViolation.Listener listener : _complianceListeners)
+        {
+            try
+            {
+                listener.onComplianceViolation(event);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("failure notifying listener {}", listener, x);
+            }
+        }
+    }
+
     @Override
     public void onComplianceViolation(ComplianceViolation.Mode mode, ComplianceViolation violation, String details)
     {
@@ -505,6 +545,14 @@ public class HttpChannelOverHttp extends HttpChannel implements HttpParser.Reque
     @Override
     public void parsedHeader(HttpField field)
     {
+        HeaderPolicy headerPolicy = getHttpConfiguration().getHeaderPolicy();
+        if (headerPolicy != null)
+        {
+            field = HeaderPolicy.apply(headerPolicy, field);
+            if (field == null)
+                return;
+        }
+
         HttpHeader header = field.getHeader();
         String value = field.getValue();
         if (header != null)
@@ -21,12 +21,15 @@ import java.util.concurrent.CopyOnWriteArrayList;
 
 import org.eclipse.jetty.http.CookieCompliance;
 import org.eclipse.jetty.http.HttpCompliance;
+import org.eclipse.jetty.http.HttpCookie;
 import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.UriCanonicalizer;
 import org.eclipse.jetty.http.UriCompliance;
 import org.eclipse.jetty.util.HostPort;
 import org.eclipse.jetty.util.Index;
 import org.eclipse.jetty.util.Jetty;
+import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.component.Dumpable;
@@ -67,6 +70,11 @@ public class HttpConfiguration implements Dumpable
     private boolean _delayDispatchUntilContent = true;
     private boolean _persistentConnectionsEnabled = true;
     private int _maxErrorDispatches = 10;
+    private int _maxPipelinedRequests = -1;
+    private int _maxRequestsPerConnection = -1;
+    private long _maxConnectionAge = -1;
+    private long _maxConnectionAgeJitter;
+    private boolean _sendKeepAliveHeader;
     private boolean _useInputDirectByteBuffers = true;
     private boolean _useOutputDirectByteBuffers = true;
     private long _minRequestDataRate;
@@ -75,11 +83,16 @@ public class HttpConfiguration implements Dumpable
     private UriCompliance _uriCompliance = UriCompliance.DEFAULT;
     private CookieCompliance _requestCookieCompliance = CookieCompliance.RFC6265;
     private CookieCompliance _responseCookieCompliance = CookieCompliance.RFC6265;
+    private HttpCookie.SameSite _cookieSameSiteDefault;
+    private boolean _cookieSecureDefault;
+    private boolean _cookiePartitionedDefault;
     private MultiPartFormDataCompliance _multiPartCompliance = MultiPartFormDataCompliance.RFC7578;
     private boolean _notifyRemoteAsyncErrors = true;
     private boolean _relativeRedirectAllowed;
     private HostPort _serverAuthority;
     private SocketAddress _localAddress;
+    private HeaderPolicy _headerPolicy;
+    private UriCanonicalizer _uriCanonicalizer;
 
     /**
      * <p>An interface that allows a request object to be customized
@@ -140,6 +153,11 @@ public class HttpConfiguration implements Dumpable
         _delayDispatchUntilContent = config._delayDispatchUntilContent;
         _persistentConnectionsEnabled = config._persistentConnectionsEnabled;
         _maxErrorDispatches = config._maxErrorDispatches;
+        _maxPipelinedRequests = config._maxPipelinedRequests;
+        _maxRequestsPerConnection = config._maxRequestsPerConnection;
+        _maxConnectionAge = config._maxConnectionAge;
+        _maxConnectionAgeJitter = config._maxConnectionAgeJitter;
+        _sendKeepAliveHeader = config._sendKeepAliveHeader;
         _useInputDirectByteBuffers = config._useInputDirectByteBuffers;
         _useOutputDirectByteBuffers = config._useOutputDirectByteBuffers;
         _minRequestDataRate = config._minRequestDataRate;
@@ -147,11 +165,16 @@ public class HttpConfiguration implements Dumpable
         _httpCompliance = config._httpCompliance;
         _requestCookieCompliance = config._requestCookieCompliance;
         _responseCookieCompliance = config._responseCookieCompliance;
+        _cookieSameSiteDefault = config._cookieSameSiteDefault;
+        _cookieSecureDefault = config._cookieSecureDefault;
+        _cookiePartitionedDefault = config._cookiePartitionedDefault;
         _notifyRemoteAsyncErrors = config._notifyRemoteAsyncErrors;
         _relativeRedirectAllowed = config._relativeRedirectAllowed;
         _uriCompliance = config._uriCompliance;
         _serverAuthority = config._serverAuthority;
         _localAddress = config._localAddress;
+        _headerPolicy = config._headerPolicy;
+        _uriCanonicalizer = config._uriCanonicalizer;
     }
 
     /**
@@ -533,6 +556,123 @@ public class HttpConfiguration implements Dumpable
         _maxErrorDispatches = max;
     }
 
+    /**
+     * <p>Returns the max number of consecutive HTTP/1.1 requests that a client may pipeline,
+     * that is send before having received the response to the previous request.</p>
+     * <p>Further pipelined requests are rejected with a 503 response,
+     * and the connection is closed.</p>
+     *
+     * @return the max number of consecutive pipelined requests, or a negative value for no limit
+     */
+    @ManagedAttribute("The max number of consecutive pipelined HTTP/1.1 requests")
+    public int getMaxPipelinedRequests()
+    {
+        return _maxPipelinedRequests;
+    }
+
+    /**
+     * @param maxPipelinedRequests the max number of consecutive pipelined requests, or a negative value for no limit
+     * @see #getMaxPipelinedRequests()
+     */
+    public void setMaxPipelinedRequests(int maxPipelinedRequests)
+    {
+        _maxPipelinedRequests = maxPipelinedRequests;
+    }
+
+    /**
+     * <p>Returns the max number of requests that can be served by a persistent HTTP/1 connection.</p>
+     * <p>The response to the last allowed request carries the {@code Connection: close} header,
+     * so that the client opens a new connection for further requests.</p>
+     *
+     * @return the max number of requests per connection, or a negative value for no limit
+     */
+    @ManagedAttribute("The max number of requests served by a persistent HTTP/1 connection")
+    public int getMaxRequestsPerConnection()
+    {
+        return _maxRequestsPerConnection;
+    }
+
+    /**
+     * @param maxRequestsPerConnection the max number of requests per connection, or a negative value for no limit
+     * @see #getMaxRequestsPerConnection()
+     */
+    public void setMaxRequestsPerConnection(int maxRequestsPerConnection)
+    {
+        _maxRequestsPerConnection = maxRequestsPerConnection;
+    }
+
+    /**
+     * <p>Returns the max age in milliseconds of a persistent HTTP/1 connection.</p>
+     * <p>Once a connection is older than its max age, the next response carries the
+     * {@code Connection: close} header, a response in progress is followed by the
+     * connection close, and an idle connection is closed.</p>
+     * <p>This allows load balancers to spread the clients across new servers,
+     * for example during a rolling restart.</p>
+     *
+     * @return the max connection age in milliseconds, or a non-positive value for no limit
+     * @see #getMaxConnectionAgeJitter()
+     */
+    @ManagedAttribute("The max age in milliseconds of a persistent HTTP/1 connection")
+    public long getMaxConnectionAge()
+    {
+        return _maxConnectionAge;
+    }
+
+    /**
+     * @param maxConnectionAge the max connection age in milliseconds, or a non-positive value for no limit
+     * @see #getMaxConnectionAge()
+     */
+    public void setMaxConnectionAge(long maxConnectionAge)
+    {
+        _maxConnectionAge = maxConnectionAge;
+    }
+
+    /**
+     * <p>Returns the max random amount of milliseconds subtracted from the
+     * {@link #getMaxConnectionAge() max connection age} of each connection.</p>
+     * <p>Connections opened at the same time, for example after a restart, are
+     * then closed at different times, so that clients do not reconnect all at once.</p>
+     *
+     * @return the max connection age jitter in milliseconds
+     */
+    @ManagedAttribute("The max random amount of milliseconds subtracted from the max connection age")
+    public long getMaxConnectionAgeJitter()
+    {
+        return _maxConnectionAgeJitter;
+    }
+
+    /**
+     * @param maxConnectionAgeJitter the max connection age jitter in milliseconds
+     * @see #getMaxConnectionAgeJitter()
+     */
+    public void setMaxConnectionAgeJitter(long maxConnectionAgeJitter)
+    {
+        _maxConnectionAgeJitter = maxConnectionAgeJitter;
+    }
+
+    /**
+     * <p>Returns whether responses on persistent HTTP/1 connections carry the {@code Keep-Alive}
+     * header, with the {@code timeout} parameter set to the idle timeout in seconds and, when
+     * {@link #getMaxRequestsPerConnection() max requests per connection} is set, the
+     * {@code max} parameter set to the number of requests that the connection can still serve.</p>
+     *
+     * @return whether to send the {@code Keep-Alive} header
+     */
+    @ManagedAttribute("Whether to send the Keep-Alive header on persistent HTTP/1 connections")
+    public boolean isSendKeepAliveHeader()
+    {
+        return _sendKeepAliveHeader;
+    }
+
+    /**
+     * @param sendKeepAliveHeader whether to send the {@code Keep-Alive} header
+     * @see #isSendKeepAliveHeader()
+     */
+    public void setSendKeepAliveHeader(boolean sendKeepAliveHeader)
+    {
+        _sendKeepAliveHeader = sendKeepAliveHeader;
+    }
+
     /**
      * @return The minimum request data rate in bytes per second; or &lt;=0 for no limit
      */
@@ -592,6 +732,28 @@ public class HttpConfiguration implements Dumpable
         _uriCompliance = uriCompliance;
     }
 
+    /**
+     * @return the canonicalizer of the request URI paths, or null
+     */
+    @ManagedAttribute("The canonicalizer of the request URI paths")
+    public UriCanonicalizer getUriCanonicalizer()
+    {
+        return _uriCanonicalizer;
+    }
+
+    /**
+     * <p>Sets the canonicalizer of the request URI paths.</p>
+     * <p>When set, the path in context of requests is the decoded canonical path, and the
+     * paths of dispatches, rewrites and transparent proxying are canonicalized as well,
+     * so that the paths rejected by the canonicalizer are rejected at every step.</p>
+     *
+     * @param uriCanonicalizer the canonicalizer, or null to only apply the {@link #getUriCompliance() URI compliance}
+     */
+    public void setUriCanonicalizer(UriCanonicalizer uriCanonicalizer)
+    {
+        _uriCanonicalizer = uriCanonicalizer;
+    }
+
     /**
      * @return The CookieCompliance used for parsing request {@code Cookie} headers.
      * @see #getResponseCookieCompliance()
@@ -626,6 +788,78 @@ public class HttpConfiguration implements Dumpable
         _responseCookieCompliance = cookieCompliance == null ? CookieCompliance.RFC6265 : cookieCompliance;
     }
 
+    /**
+     * @return the SameSite attribute of response cookies that do not specify one, or null for none
+     * @see HttpCookie#SAME_SITE_DEFAULT_ATTRIBUTE
+     */
+    @ManagedAttribute("The SameSite attribute of response cookies that do not specify one")
+    public HttpCookie.SameSite getCookieSameSiteDefault()
+    {
+        return _cookieSameSiteDefault;
+    }
+
+    /**
+     * <p>Sets the SameSite attribute of response cookies that do not specify one.</p>
+     * <p>A context may override this default with the {@link HttpCookie#SAME_SITE_DEFAULT_ATTRIBUTE} attribute.</p>
+     *
+     * @param sameSite the default SameSite attribute, or null for none
+     */
+    public void setCookieSameSiteDefault(HttpCookie.SameSite sameSite)
+    {
+        _cookieSameSiteDefault = sameSite;
+    }
+
+    /**
+     * @param sameSite the name of the default SameSite attribute, case insensitive, or null or empty for none
+     */
+    public void setCookieSameSiteDefault(String sameSite)
+    {
+        setCookieSameSiteDefault(StringUtil.isBlank(sameSite) ? null : HttpCookie.SameSite.valueOf(StringUtil.asciiToUpperCase(sameSite.trim())));
+    }
+
+    /**
+     * @return whether response cookies to secure requests are made Secure
+     * @see HttpCookie#SECURE_DEFAULT_ATTRIBUTE
+     */
+    @ManagedAttribute("Whether response cookies to secure requests are made Secure")
+    public boolean isCookieSecureDefault()
+    {
+        return _cookieSecureDefault;
+    }
+
+    /**
+     * <p>Sets whether response cookies are made Secure when the request is secure.</p>
+     * <p>A context may override this default with the {@link HttpCookie#SECURE_DEFAULT_ATTRIBUTE} attribute.</p>
+     *
+     * @param secure whether response cookies to secure requests are made Secure
+     */
+    public void setCookieSecureDefault(boolean secure)
+    {
+        _cookieSecureDefault = secure;
+    }
+
+    /**
+     * @return whether Secure response cookies with SameSite=None are made Partitioned
+     * @see HttpCookie#PARTITIONED_DEFAULT_ATTRIBUTE
+     */
+    @ManagedAttribute("Whether Secure response cookies with SameSite=None are made Partitioned")
+    public boolean isCookiePartitionedDefault()
+    {
+        return _cookiePartitionedDefault;
+    }
+
+    /**
+     * <p>Sets whether cross site response cookies, that is Secure cookies with SameSite=None,
+     * are made Partitioned as defined by CHIPS.</p>
+     * <p>A context may override this default with the {@link HttpCookie#PARTITIONED_DEFAULT_ATTRIBUTE} attribute.</p>
+     *
+     * @param partitioned whether cross site response cookies are made Partitioned
+     */
+    public void setCookiePartitionedDefault(boolean partitioned)
+    {
+        _cookiePartitionedDefault = partitioned;
+    }
+
     /**
      * Sets the compliance level for multipart/form-data handling.
      *
@@ -738,6 +972,26 @@ public class HttpConfiguration implements Dumpable
             _serverAuthority = authority;
     }
 
+    /**
+     * @return the policy applied to the request header fields, or null
+     */
+    @ManagedAttribute("The policy applied to the request header fields")
+    public HeaderPolicy getHeaderPolicy()
+    {
+        return _headerPolicy;
+    }
+
+    /**
+     * <p>Sets the policy applied to the request header fields while they are parsed,
+     * before the request is customized and handled.</p>
+     *
+     * @param headerPolicy the request header policy, or null for no policy
+     */
+    public void setHeaderPolicy(HeaderPolicy headerPolicy)
+    {
+        _headerPolicy = headerPolicy;
+    }
+
     @Override
     public String dump()
     {
@@ -749,6 +1003,8 @@ public class HttpConfiguration implements Dumpable
     {
         Dumpable.dumpObjects(out, indent, this,
             new DumpableCollection("customizers", _customizers),
+            "headerPolicy=" + _headerPolicy,
+            "uriCanonicalizer=" + _uriCanonicalizer,
             new DumpableCollection("formEncodedMethods", _formEncodedMethods.keySet()),
             "outputBufferSize=" + _outputBufferSize,
             "outputAggregationSize=" + _outputAggregationSize,
@@ -764,10 +1020,18 @@ public class HttpConfiguration implements Dumpable
             "delayDispatchUntilContent=" + _delayDispatchUntilContent,
             "persistentConnectionsEnabled=" + _persistentConnectionsEnabled,
             "maxErrorDispatches=" + _maxErrorDispatches,
+            "maxPipelinedRequests=" + _maxPipelinedRequests,
+            "maxRequestsPerConnection=" + _maxRequestsPerConnection,
+            "maxConnectionAge=" + _maxConnectionAge,
+            "maxConnectionAgeJitter=" + _maxConnectionAgeJitter,
+            "sendKeepAliveHeader=" + _sendKeepAliveHeader,
             "minRequestDataRate=" + _minRequestDataRate,
             "minResponseDataRate=" + _minResponseDataRate,
             "requestCookieCompliance=" + _requestCookieCompliance,
             "responseCookieCompliance=" + _responseCookieCompliance,
+            "cookieSameSiteDefault=" + _cookieSameSiteDefault,
+            "cookieSecureDefault=" + _cookieSecureDefault,
+            "cookiePartitionedDefault=" + _cookiePartitionedDefault,
             "notifyRemoteAsyncErrors=" + _notifyRemoteAsyncErrors,
             "relativeRedirectAllowed=" + _relativeRedirectAllowed
         );
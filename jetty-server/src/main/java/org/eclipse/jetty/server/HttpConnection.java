@@ -15,8 +15,15 @@ package org.eclipse.jetty.server;
 
 import java.io.IOException;
 import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.channels.SocketChannel;
 import java.nio.channels.WritePendingException;
+import java.util.EventListener;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.RejectedExecutionException;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.LongAdder;
 
 import org.eclipse.jetty.http.BadMessageException;
@@ -36,10 +43,13 @@ import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.EofException;
 import org.eclipse.jetty.io.RetainableByteBuffer;
 import org.eclipse.jetty.io.RetainableByteBufferPool;
+import org.eclipse.jetty.io.SocketChannelEndPoint;
 import org.eclipse.jetty.io.WriteFlusher;
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.Scheduler;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -68,6 +78,13 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
     private final boolean _recordHttpComplianceViolations;
     private final LongAdder bytesIn = new LongAdder();
     private final LongAdder bytesOut = new LongAdder();
+    private final List<PipelineListener> _pipelineListeners = new CopyOnWriteArrayList<>();
+    private final LongAdder _pipelinedRequests = new LongAdder();
+    private final LongAdder _pipelineBlockedTime = new LongAdder();
+    private volatile int _pipelineDepth;
+    private volatile int _maxPipelineDepth;
+    private long _maxAgeNanoTime;
+    private Scheduler.Task _maxAgeTask;
     private boolean _useInputDirectByteBuffers;
     private boolean _useOutputDirectByteBuffers;
 
@@ -177,6 +194,54 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
         return getHttpChannel().getRequests();
     }
 
+    /**
+     * @return the number of requests received on this connection before the response to the previous request was complete
+     */
+    public long getPipelinedRequests()
+    {
+        return _pipelinedRequests.longValue();
+    }
+
+    /**
+     * @return the current number of consecutive pipelined requests on this connection
+     */
+    public int getPipelineDepth()
+    {
+        return _pipelineDepth;
+    }
+
+    /**
+     * @return the max number of consecutive pipelined requests on this connection
+     */
+    public int getMaxPipelineDepth()
+    {
+        return _maxPipelineDepth;
+    }
+
+    /**
+     * @return the total time in ms that pipelined requests may have been blocked behind the previous responses
+     * @see PipelineListener#onPipelineBlocked(HttpConnection, int, long)
+     */
+    public long getPipelineBlockedTime()
+    {
+        return _pipelineBlockedTime.longValue();
+    }
+
+    @Override
+    public void addEventListener(EventListener listener)
+    {
+        super.addEventListener(listener);
+        if (listener instanceof PipelineListener)
+            _pipelineListeners.add((PipelineListener)listener);
+    }
+
+    @Override
+    public void removeEventListener(EventListener listener)
+    {
+        super.removeEventListener(listener);
+        _pipelineListeners.remove(listener);
+    }
+
     public boolean isUseInputDirectByteBuffers()
     {
         return _useInputDirectByteBuffers;
@@ -470,6 +535,7 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
         }
 
         // Reset the channel, parsers and generator
+        long requestTimeStamp = _channel.getRequest().getTimeStamp();
         _channel.recycle();
         if (!_parser.isClosed())
         {
@@ -481,6 +547,12 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
 
         _generator.reset();
 
+        // If the next request has already been received, it was pipelined.
+        if (_parser.isStart() && !isRequestBufferEmpty())
+            onPipelined(requestTimeStamp);
+        else
+            _pipelineDepth = 0;
+
         // if we are not called from the onfillable thread, schedule completion
         if (getCurrentConnection() != this)
         {
@@ -521,6 +593,88 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
         }
     }
 
+    private void onPipelined(long requestTimeStamp)
+    {
+        int depth = ++_pipelineDepth;
+        if (depth > _maxPipelineDepth)
+            _maxPipelineDepth = depth;
+        _pipelinedRequests.increment();
+        // The pipelined request may have been received at any time during the
+        // previous exchange, whose duration is the upper bound of the blocked time.
+        long blocked = requestTimeStamp > 0 ? Math.max(0, System.currentTimeMillis() - requestTimeStamp) : 0;
+        _pipelineBlockedTime.add(blocked);
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} pipelined request at depth {} blocked for up to {}ms", this, depth, blocked);
+        for (PipelineListener listener : _pipelineListeners)
+        {
+            try
+            {
+                listener.onPipelineBlocked(this, depth, blocked);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    boolean isPipelineLimitExceeded()
+    {
+        int maxPipelinedRequests = getHttpConfiguration().getMaxPipelinedRequests();
+        return maxPipelinedRequests >= 0 && _pipelineDepth > maxPipelinedRequests;
+    }
+
+    /**
+     * @return whether the current request is the last one allowed by the
+     * max requests per connection or the max connection age
+     * @see HttpConfiguration#getMaxRequestsPerConnection()
+     * @see HttpConfiguration#getMaxConnectionAge()
+     */
+    boolean isKeepAliveExhausted()
+    {
+        int maxRequestsPerConnection = getHttpConfiguration().getMaxRequestsPerConnection();
+        if (maxRequestsPerConnection >= 0 && _channel.getRequests() >= maxRequestsPerConnection)
+            return true;
+        return isMaxAgeExpired();
+    }
+
+    private boolean isMaxAgeExpired()
+    {
+        return _maxAgeTask != null && NanoTime.isBeforeOrSame(_maxAgeNanoTime, NanoTime.now());
+    }
+
+    /**
+     * @return the value of the {@code Keep-Alive} response header, or null if there is nothing to advertise
+     * @see HttpConfiguration#isSendKeepAliveHeader()
+     */
+    String getKeepAliveHeaderValue()
+    {
+        StringBuilder builder = new StringBuilder();
+        long idleTimeout = TimeUnit.MILLISECONDS.toSeconds(getEndPoint().getIdleTimeout());
+        if (idleTimeout > 0)
+            builder.append("timeout=").append(idleTimeout);
+        int maxRequestsPerConnection = getHttpConfiguration().getMaxRequestsPerConnection();
+        if (maxRequestsPerConnection >= 0)
+        {
+            if (builder.length() > 0)
+                builder.append(", ");
+            builder.append("max=").append(Math.max(0L, maxRequestsPerConnection - _channel.getRequests()));
+        }
+        return builder.length() == 0 ? null : builder.toString();
+    }
+
+    private void onMaxAgeExpired()
+    {
+        // Only idle connections are closed here, busy connections are
+        // closed when the response of the current request is complete.
+        if (_channel.getState().isIdle() && _parser.isStart() && isRequestBufferEmpty())
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Max connection age expired, closing idle {}", this);
+            close();
+        }
+    }
+
     @Override
     protected boolean onReadTimeout(Throwable timeout)
     {
@@ -538,6 +692,17 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
     public void onOpen()
     {
         super.onOpen();
+        long maxAge = getHttpConfiguration().getMaxConnectionAge();
+        if (maxAge > 0)
+        {
+            // Randomly shorten the max age, so that connections opened
+            // at the same time are not all closed at the same time.
+            long jitter = Math.min(getHttpConfiguration().getMaxConnectionAgeJitter(), maxAge);
+            if (jitter > 0)
+                maxAge -= ThreadLocalRandom.current().nextLong(jitter);
+            _maxAgeNanoTime = NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(maxAge);
+            _maxAgeTask = getConnector().getScheduler().schedule(this::onMaxAgeExpired, maxAge, TimeUnit.MILLISECONDS);
+        }
         if (isRequestBufferEmpty())
             fillInterested();
         else
@@ -547,6 +712,9 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
     @Override
     public void onClose(Throwable cause)
     {
+        Scheduler.Task maxAgeTask = _maxAgeTask;
+        if (maxAgeTask != null)
+            maxAgeTask.cancel();
         if (cause == null)
             _sendCallback.close();
         else
@@ -612,6 +780,32 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
         LOG.debug("ignore push in {}", this);
     }
 
+    /**
+     * @return true if the endpoint is a plain, non TLS, socket channel
+     * and the current request is not a HEAD request
+     */
+    @Override
+    public boolean isSendFileSupported()
+    {
+        return getEndPoint() instanceof SocketChannelEndPoint && !_channel.getRequest().isHead();
+    }
+
+    @Override
+    public void sendFile(FileChannel file, long position, long length, Callback callback)
+    {
+        if (!isSendFileSupported())
+        {
+            callback.failed(new UnsupportedOperationException());
+            return;
+        }
+        if (!_generator.isCommitted() || _generator.isChunking())
+        {
+            callback.failed(new IllegalStateException("Response not committed with Content-Length"));
+            return;
+        }
+        new SendFileCallback(file, position, length, callback).iterate();
+    }
+
     public void asyncReadFillInterested()
     {
         getEndPoint().tryFillInterested(_asyncReadCallback);
@@ -632,14 +826,39 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
     @Override
     public String toConnectionString()
     {
-        return String.format("%s@%x[p=%s,g=%s]=>%s",
+        return String.format("%s@%x[p=%s,g=%s,pipelined=%d,depth=%d/%d]=>%s",
             getClass().getSimpleName(),
             hashCode(),
             _parser,
             _generator,
+            getPipelinedRequests(),
+            _pipelineDepth,
+            _maxPipelineDepth,
             _channel);
     }
 
+    /**
+     * <p>A listener for HTTP/1.1 pipelining events.</p>
+     * <p>Instances of this listener added as beans to a connector or to a
+     * connection factory are added to every {@link HttpConnection}, similarly
+     * to {@link Connection.Listener}s.</p>
+     */
+    public interface PipelineListener extends EventListener
+    {
+        /**
+         * <p>Invoked when a pipelined request has been received while the
+         * response to the previous request was not complete.</p>
+         * <p>HTTP/1.1 responses must be sent in request order, so the
+         * pipelined request has not been processed until now.</p>
+         *
+         * @param connection the connection
+         * @param depth the number of consecutive pipelined requests
+         * @param blockedMillis the upper bound of the time in ms the pipelined
+         * request has been blocked, that is the duration of the previous exchange
+         */
+        public void onPipelineBlocked(HttpConnection connection, int depth, long blockedMillis);
+    }
+
     private class Content extends HttpInput.Content
     {
         public Content(ByteBuffer content)
@@ -687,6 +906,81 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
         }
     }
 
+    /**
+     * <p>Transfers the file content directly to the socket channel with
+     * {@link FileChannel#transferTo(long, long, java.nio.channels.WritableByteChannel)}.</p>
+     * <p>When the socket buffer is full, the next region of the file is memory mapped
+     * and written via the endpoint, so that the write completes asynchronously
+     * when the socket becomes writable again.</p>
+     */
+    private class SendFileCallback extends IteratingCallback
+    {
+        private final FileChannel _file;
+        private final Callback _callback;
+        private long _position;
+        private long _remaining;
+
+        private SendFileCallback(FileChannel file, long position, long length, Callback callback)
+        {
+            _file = file;
+            _callback = callback;
+            _position = position;
+            _remaining = length;
+        }
+
+        @Override
+        public InvocationType getInvocationType()
+        {
+            return _callback.getInvocationType();
+        }
+
+        @Override
+        protected Action process() throws Exception
+        {
+            SocketChannelEndPoint endPoint = (SocketChannelEndPoint)getEndPoint();
+            SocketChannel channel = endPoint.getChannel();
+            while (_remaining > 0)
+            {
+                long transferred = _file.transferTo(_position, _remaining, channel);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("sendfile transferred {}/{} at {} on {}", transferred, _remaining, _position, HttpConnection.this);
+                if (transferred > 0)
+                {
+                    _position += transferred;
+                    _remaining -= transferred;
+                    bytesOut.add(transferred);
+                    endPoint.notIdle();
+                    continue;
+                }
+
+                if (_position >= _file.size())
+                    throw new EofException("Unexpected end of file at " + _position);
+
+                // The socket buffer is full, write a mapped region of the file
+                // so that the endpoint waits for the socket to be writable.
+                long length = Math.min(_remaining, Math.max(_config.getOutputBufferSize(), 8192));
+                ByteBuffer buffer = _file.map(FileChannel.MapMode.READ_ONLY, _position, length);
+                _position += length;
+                _remaining -= length;
+                endPoint.write(this, buffer);
+                return Action.SCHEDULED;
+            }
+            return Action.SUCCEEDED;
+        }
+
+        @Override
+        protected void onCompleteSuccess()
+        {
+            _callback.succeeded();
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable cause)
+        {
+            _callback.failed(cause);
+        }
+    }
+
     private class SendCallback extends IteratingCallback
     {
         private MetaData.Response _info;
@@ -845,10 +1139,10 @@ public class HttpConnection extends AbstractConnection implements Runnable, Http
                     }
                     case DONE:
                     {
-                        // If this is the end of the response and the connector was shutdown after response was committed,
-                        // we can't add the Connection:close header, but we are still allowed to close the connection
-                        // by shutting down the output.
-                        if (getConnector().isShutdown() && _generator.isEnd() && _generator.isPersistent())
+                        // If this is the end of the response and the connector was shutdown or the connection max age
+                        // expired after response was committed, we can't add the Connection:close header, but we are
+                        // still allowed to close the connection by shutting down the output.
+                        if ((getConnector().isShutdown() || isMaxAgeExpired()) && _generator.isEnd() && _generator.isPersistent())
                             _shutdownOut = true;
 
                         return Action.SUCCEEDED;
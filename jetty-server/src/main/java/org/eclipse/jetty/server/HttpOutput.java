@@ -17,6 +17,7 @@ import java.io.IOException;
 import java.io.InputStream;
 import java.nio.ByteBuffer;
 import java.nio.CharBuffer;
+import java.nio.channels.FileChannel;
 import java.nio.channels.ReadableByteChannel;
 import java.nio.channels.WritePendingException;
 import java.nio.charset.Charset;
@@ -1172,6 +1173,24 @@ public class HttpOutput extends ServletOutputStream implements Runnable
         }
     }
 
+    /**
+     * Blocking send of a region of a file, without copying it to user-space buffers.
+     *
+     * @param file The file to send the content from
+     * @param position The position in the file of the first byte to send
+     * @param length The number of bytes to send
+     * @throws IOException if the send fails
+     * @see #sendContent(FileChannel, long, long, Callback)
+     */
+    public void sendContent(FileChannel file, long position, long length) throws IOException
+    {
+        try (Blocker blocker = _writeBlocker.acquire())
+        {
+            sendContent(file, position, length, blocker);
+            blocker.block();
+        }
+    }
+
     /**
      * Blocking send of HTTP content.
      *
@@ -1250,6 +1269,47 @@ public class HttpOutput extends ServletOutputStream implements Runnable
             new ReadableByteChannelWritingCB(in, callback).iterate();
     }
 
+    /**
+     * @return whether the content can be sent with {@link #sendContent(FileChannel, long, long, Callback)},
+     * that is whether there is no {@link Interceptor} (for example a gzip interceptor)
+     * and the transport supports it
+     * @see HttpChannel#isSendFileSupported()
+     */
+    public boolean isSendFileSupported()
+    {
+        return _interceptor == _channel && _channel.isSendFileSupported();
+    }
+
+    /**
+     * <p>Asynchronous send of a region of a file, without copying it to user-space buffers.</p>
+     * <p>The response headers, which must include a Content-Length equal to {@code length},
+     * are committed before the file content is sent.
+     * The file will be closed after sending all content.</p>
+     *
+     * @param file The file to send the content from
+     * @param position The position in the file of the first byte to send
+     * @param length The number of bytes to send
+     * @param callback The callback to use to notify success or failure
+     * @see #isSendFileSupported()
+     */
+    public void sendContent(FileChannel file, long position, long length, Callback callback)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("sendContent(file={},{},{},{})", file, position, length, callback);
+
+        if (!isSendFileSupported())
+        {
+            IO.close(file);
+            callback.failed(new IOException("cannot sendContent(FileChannel), not supported"));
+            return;
+        }
+
+        if (prepareSendContent(0, callback))
+            new FileChannelWritingCB(file, position, length, callback).iterate();
+        else
+            IO.close(file);
+    }
+
     private boolean prepareSendContent(int len, Callback callback)
     {
         try (AutoLock l = _channelState.lock())
@@ -1868,6 +1928,65 @@ public class HttpOutput extends ServletOutputStream implements Runnable
         }
     }
 
+    /**
+     * An iterating callback that commits the response, then sends
+     * a region of a file via {@link HttpChannel#sendFile(FileChannel, long, long, Callback)},
+     * and finally completes the response.
+     */
+    private class FileChannelWritingCB extends NestedChannelWriteCB
+    {
+        private final FileChannel _file;
+        private final long _position;
+        private final long _length;
+        private boolean _committed;
+        private boolean _sent;
+        private boolean _completed;
+
+        private FileChannelWritingCB(FileChannel file, long position, long length, Callback callback)
+        {
+            super(callback, true);
+            _file = file;
+            _position = position;
+            _length = length;
+        }
+
+        @Override
+        protected Action process() throws Exception
+        {
+            if (!_committed)
+            {
+                _committed = true;
+                channelWrite(BufferUtil.EMPTY_BUFFER, false, this);
+                return Action.SCHEDULED;
+            }
+
+            if (!_sent)
+            {
+                _sent = true;
+                _written += _length;
+                _channel.sendFile(_file, _position, _length, this);
+                return Action.SCHEDULED;
+            }
+
+            if (!_completed)
+            {
+                _completed = true;
+                IO.close(_file);
+                channelWrite(BufferUtil.EMPTY_BUFFER, true, this);
+                return Action.SCHEDULED;
+            }
+
+            return Action.SUCCEEDED;
+        }
+
+        @Override
+        public void onCompleteFailure(Throwable x)
+        {
+            IO.close(_file);
+            super.onCompleteFailure(x);
+        }
+    }
+
     private static class WriteBlocker extends SharedBlockingCallback
     {
         private final HttpChannel _channel;
@@ -14,6 +14,7 @@
 package org.eclipse.jetty.server;
 
 import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
 
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.util.Callback;
@@ -47,6 +48,34 @@ public interface HttpTransport
      */
     void push(MetaData.Request request);
 
+    /**
+     * @return true if the content of the current, committed, response can be
+     * sent with {@link #sendFile(FileChannel, long, long, Callback)}
+     */
+    default boolean isSendFileSupported()
+    {
+        return false;
+    }
+
+    /**
+     * <p>Asynchronous call to send, without copying it to user-space buffers,
+     * a region of a file as content of the current response.</p>
+     * <p>The response must have already been committed with a known content
+     * length, and the content must not be the last content: the response
+     * is completed by a subsequent call to
+     * {@link #send(MetaData.Request, MetaData.Response, ByteBuffer, boolean, Callback)}.</p>
+     *
+     * @param file the file to send the content from
+     * @param position the position in the file of the first byte to send
+     * @param length the number of bytes to send
+     * @param callback The Callback instance that success or failure of the send is notified on
+     * @see #isSendFileSupported()
+     */
+    default void sendFile(FileChannel file, long position, long length, Callback callback)
+    {
+        callback.failed(new UnsupportedOperationException());
+    }
+
     /**
      * Called to indicated the end of the current request/response cycle (which may be
      * some time after the last content is sent).
@@ -37,6 +37,7 @@ import javax.servlet.MultipartConfigElement;
 import javax.servlet.ServletInputStream;
 import javax.servlet.http.Part;
 
+import org.eclipse.jetty.http.ContentDisposition;
 import org.eclipse.jetty.server.MultiParts.NonCompliance;
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.util.BufferUtil;
@@ -716,29 +717,25 @@ public class MultiPartFormInputStream
             try
             {
                 // Extract content-disposition
-                boolean formData = false;
                 if (contentDisposition == null)
                 {
                     throw new IOException("Missing content-disposition");
                 }
 
-                QuotedStringTokenizer tok = new QuotedStringTokenizer(contentDisposition, ";", false, true);
-                String name = null;
-                String filename = null;
-                while (tok.hasMoreTokens())
+                ContentDisposition disposition;
+                try
+                {
+                    disposition = ContentDisposition.parse(contentDisposition);
+                }
+                catch (IllegalArgumentException x)
                 {
-                    String t = tok.nextToken().trim();
-                    String tl = StringUtil.asciiToLowerCase(t);
-                    if (tl.startsWith("form-data"))
-                        formData = true;
-                    else if (tl.startsWith("name="))
-                        name = value(t);
-                    else if (tl.startsWith("filename="))
-                        filename = filenameValue(t);
+                    throw new IOException("Bad content-disposition", x);
                 }
+                String name = disposition.getName();
+                String filename = disposition.getFileName();
 
                 // Check disposition
-                if (!formData)
+                if (!disposition.isFormData())
                     throw new IOException("Part not form-data");
 
                 // It is valid for reset and submit buttons to have an empty name.
@@ -872,39 +869,13 @@ public class MultiPartFormInputStream
         return false;
     }
 
-    private static String value(String nameEqualsValue)
+    static String value(String nameEqualsValue)
     {
         int idx = nameEqualsValue.indexOf('=');
         String value = nameEqualsValue.substring(idx + 1).trim();
         return QuotedStringTokenizer.unquoteOnly(value);
     }
 
-    private static String filenameValue(String nameEqualsValue)
-    {
-        int idx = nameEqualsValue.indexOf('=');
-        String value = nameEqualsValue.substring(idx + 1).trim();
-
-        if (value.matches(".??[a-z,A-Z]\\:\\\\[^\\\\].*"))
-        {
-            // incorrectly escaped IE filenames that have the whole path
-            // we just strip any leading & trailing quotes and leave it as is
-            char first = value.charAt(0);
-            if (first == '"' || first == '\'')
-                value = value.substring(1);
-            char last = value.charAt(value.length() - 1);
-            if (last == '"' || last == '\'')
-                value = value.substring(0, value.length() - 1);
-
-            return value;
-        }
-        else
-            // unquote the string, but allow any backslashes that don't
-            // form a valid escape sequence to remain as many browsers
-            // even on *nix systems will not escape a filename containing
-            // backslashes
-            return QuotedStringTokenizer.unquoteOnly(value, true);
-    }
-
     /**
      * @return the size of buffer used to read data from the input stream
      */
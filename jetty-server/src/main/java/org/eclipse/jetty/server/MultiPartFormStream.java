@@ -0,0 +1,799 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.File;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.SeekableByteChannel;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.nio.file.StandardOpenOption;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.atomic.AtomicBoolean;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ReadListener;
+import javax.servlet.ServletInputStream;
+import javax.servlet.http.HttpServletRequest;
+
+import org.eclipse.jetty.http.ContentDisposition;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.io.EofException;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.QuotedStringTokenizer;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A streaming parser for {@code multipart/form-data} request content.</p>
+ * <p>Differently from {@link HttpServletRequest#getParts()}, that stores all the
+ * parts in memory or on disk before returning them, this class delivers the content
+ * of each part to a {@link Sink} as soon as it is read from the network.
+ * The content is read asynchronously and with backpressure: more request content
+ * is read only when the sink completes the callback of the previous write.</p>
+ * <p>The request must be in asynchronous mode before calling {@link #parse(Listener)}:</p>
+ * <pre>{@code
+ * AsyncContext asyncContext = request.startAsync();
+ * MultiPartFormStream multiPart = new MultiPartFormStream(request);
+ * multiPart.setMaxPartSize(16 * 1024 * 1024);
+ * multiPart.parse(new MultiPartFormStream.Listener()
+ * {
+ *     @Override
+ *     public MultiPartFormStream.Sink onPart(MultiPartFormStream.Part part) throws IOException
+ *     {
+ *         return multiPart.newPathSink();
+ *     }
+ *
+ *     @Override
+ *     public void onComplete()
+ *     {
+ *         asyncContext.complete();
+ *     }
+ *
+ *     @Override
+ *     public void onFailure(Throwable failure)
+ *     {
+ *         asyncContext.complete();
+ *     }
+ * });
+ * }</pre>
+ * <p>Files created by {@link #newPathSink()} are stored in the {@link #getTempDirectory()
+ * temporary directory}, and are deleted when the request completes, unless they have been
+ * {@link PathSink#moveTo(Path) moved}.</p>
+ *
+ * @see MultiPartFormInputStream
+ */
+public class MultiPartFormStream
+{
+    /**
+     * The name of the context attribute, or context init parameter, that
+     * specifies the directory where the parts content is stored.
+     */
+    public static final String TEMP_DIRECTORY_ATTRIBUTE = "org.eclipse.jetty.server.multipart.tempDirectory";
+
+    private static final Logger LOG = LoggerFactory.getLogger(MultiPartFormStream.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final List<PathSink> _pathSinks = new ArrayList<>();
+    private final Request _request;
+    private final String _boundary;
+    private long _maxPartSize = -1;
+    private long _maxContentSize = -1;
+    private int _maxParts;
+    private int _bufferSize = 16 * 1024;
+    private Path _tempDirectory;
+    private Parser _parser;
+    private boolean _completed;
+
+    /**
+     * @param request the {@code multipart/form-data} request to parse
+     * @throws IllegalArgumentException if the request is not a {@code multipart/form-data} request
+     */
+    public MultiPartFormStream(HttpServletRequest request)
+    {
+        _request = Request.getBaseRequest(request);
+        if (_request == null)
+            throw new IllegalArgumentException("Unsupported request " + request);
+        String contentType = request.getContentType();
+        if (contentType == null || !MimeTypes.Type.MULTIPART_FORM_DATA.is(HttpField.valueParameters(contentType, null)))
+            throw new IllegalArgumentException("Unsupported Content-Type [" + contentType + "], expected [multipart/form-data]");
+
+        String boundary = "";
+        int bstart = contentType.indexOf("boundary=");
+        if (bstart >= 0)
+        {
+            int bend = contentType.indexOf(";", bstart);
+            bend = (bend < 0 ? contentType.length() : bend);
+            boundary = QuotedStringTokenizer.unquote(MultiPartFormInputStream.value(contentType.substring(bstart, bend)).trim());
+        }
+        _boundary = boundary;
+
+        ContextHandler.Context context = _request.getContext();
+        _maxParts = context == null ? ContextHandler.DEFAULT_MAX_FORM_KEYS : context.getContextHandler().getMaxFormKeys();
+    }
+
+    /**
+     * @return the max size in bytes of the content of a single part, or -1 for unlimited size
+     */
+    public long getMaxPartSize()
+    {
+        return _maxPartSize;
+    }
+
+    /**
+     * @param maxPartSize the max size in bytes of the content of a single part, or -1 for unlimited size
+     */
+    public void setMaxPartSize(long maxPartSize)
+    {
+        _maxPartSize = maxPartSize;
+    }
+
+    /**
+     * @return the max size in bytes of the content of all the parts, or -1 for unlimited size
+     */
+    public long getMaxContentSize()
+    {
+        return _maxContentSize;
+    }
+
+    /**
+     * @param maxContentSize the max size in bytes of the content of all the parts, or -1 for unlimited size
+     */
+    public void setMaxContentSize(long maxContentSize)
+    {
+        _maxContentSize = maxContentSize;
+    }
+
+    /**
+     * @return the max number of parts, or -1 for unlimited parts
+     */
+    public int getMaxParts()
+    {
+        return _maxParts;
+    }
+
+    /**
+     * <p>Sets the max number of parts.</p>
+     * <p>Defaults to the context {@link ContextHandler#getMaxFormKeys() max form keys}.</p>
+     *
+     * @param maxParts the max number of parts, or -1 for unlimited parts
+     */
+    public void setMaxParts(int maxParts)
+    {
+        _maxParts = maxParts;
+    }
+
+    /**
+     * @return the size of buffer used to read the request content
+     */
+    public int getBufferSize()
+    {
+        return _bufferSize;
+    }
+
+    /**
+     * @param bufferSize the size of buffer used to read the request content
+     */
+    public void setBufferSize(int bufferSize)
+    {
+        _bufferSize = bufferSize;
+    }
+
+    /**
+     * <p>Returns the directory where {@link #newPathSink()} stores the parts content.</p>
+     * <p>If not explicitly {@link #setTempDirectory(Path) set}, the directory is, in order:</p>
+     * <ul>
+     * <li>the value of the {@link #TEMP_DIRECTORY_ATTRIBUTE} context attribute or context init parameter</li>
+     * <li>the context temporary directory, {@code javax.servlet.context.tempdir}</li>
+     * <li>the {@code java.io.tmpdir} directory</li>
+     * </ul>
+     *
+     * @return the directory where the parts content is stored
+     */
+    public Path getTempDirectory()
+    {
+        if (_tempDirectory != null)
+            return _tempDirectory;
+        ContextHandler.Context context = _request.getContext();
+        if (context != null)
+        {
+            Path tempDirectory = toPath(context.getAttribute(TEMP_DIRECTORY_ATTRIBUTE));
+            if (tempDirectory == null)
+                tempDirectory = toPath(context.getInitParameter(TEMP_DIRECTORY_ATTRIBUTE));
+            if (tempDirectory == null)
+                tempDirectory = toPath(context.getAttribute("javax.servlet.context.tempdir"));
+            if (tempDirectory != null)
+                return tempDirectory;
+        }
+        return Path.of(System.getProperty("java.io.tmpdir"));
+    }
+
+    /**
+     * @param tempDirectory the directory where the parts content is stored
+     */
+    public void setTempDirectory(Path tempDirectory)
+    {
+        _tempDirectory = tempDirectory;
+    }
+
+    private static Path toPath(Object value)
+    {
+        if (value instanceof Path)
+            return (Path)value;
+        if (value instanceof File)
+            return ((File)value).toPath();
+        if (value instanceof String && !StringUtil.isBlank((String)value))
+            return Path.of((String)value);
+        return null;
+    }
+
+    /**
+     * <p>Starts parsing the request content, notifying the given listener of the parts.</p>
+     *
+     * @param listener the listener notified of the parts
+     * @throws IOException if the request content cannot be read
+     * @throws IllegalStateException if the request is not in asynchronous mode, or parsing was already started
+     */
+    public void parse(Listener listener) throws IOException
+    {
+        Parser parser = new Parser(listener);
+        try (AutoLock l = _lock.lock())
+        {
+            if (_parser != null)
+                throw new IllegalStateException("Parsing already started");
+            _parser = parser;
+        }
+
+        _request.getAsyncContext().addListener(new AsyncListener()
+        {
+            @Override
+            public void onStartAsync(AsyncEvent event)
+            {
+                event.getAsyncContext().addListener(this);
+            }
+
+            @Override
+            public void onTimeout(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onError(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onComplete(AsyncEvent event)
+            {
+                onRequestComplete();
+            }
+        });
+
+        ServletInputStream input = _request.getInputStream();
+        parser.setInput(input);
+        input.setReadListener(parser);
+    }
+
+    /**
+     * <p>Creates a {@link Sink} that stores the part content in a file in
+     * the {@link #getTempDirectory() temporary directory}.</p>
+     * <p>The file is deleted when the request completes, unless
+     * it has been {@link PathSink#moveTo(Path) moved}.</p>
+     *
+     * @return a new sink that stores the part content in a file
+     * @throws IOException if the file cannot be created
+     */
+    public PathSink newPathSink() throws IOException
+    {
+        Path tempDirectory = getTempDirectory();
+        if (!Files.exists(tempDirectory))
+            Files.createDirectories(tempDirectory);
+        PathSink sink = new PathSink(Files.createTempFile(tempDirectory, "MultiPart", ""));
+        boolean completed;
+        try (AutoLock l = _lock.lock())
+        {
+            completed = _completed;
+            if (!completed)
+                _pathSinks.add(sink);
+        }
+        if (completed)
+        {
+            sink.cleanUp();
+            throw new IllegalStateException("Request completed");
+        }
+        return sink;
+    }
+
+    private void onRequestComplete()
+    {
+        Parser parser;
+        List<PathSink> pathSinks;
+        try (AutoLock l = _lock.lock())
+        {
+            _completed = true;
+            parser = _parser;
+            pathSinks = new ArrayList<>(_pathSinks);
+            _pathSinks.clear();
+        }
+
+        if (parser != null)
+            parser.abort(new EofException("Request completed"));
+
+        for (PathSink sink : pathSinks)
+        {
+            sink.cleanUp();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[boundary=%s]", getClass().getSimpleName(), hashCode(), _boundary);
+    }
+
+    /**
+     * <p>A listener for the parts of the {@code multipart/form-data} content.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Invoked when the headers of a part have been parsed.</p>
+         *
+         * @param part the part
+         * @return the sink that receives the part content, or {@code null} to discard the part content
+         * @throws IOException if the sink cannot be created
+         */
+        Sink onPart(Part part) throws IOException;
+
+        /**
+         * <p>Invoked when all the parts have been parsed and their content written to their sinks.</p>
+         */
+        default void onComplete()
+        {
+        }
+
+        /**
+         * <p>Invoked when the parsing fails, for example because the content
+         * is malformed, or a quota has been exceeded, or a sink failed.</p>
+         *
+         * @param failure the failure
+         */
+        default void onFailure(Throwable failure)
+        {
+        }
+    }
+
+    /**
+     * <p>The destination of the content of a part.</p>
+     */
+    public interface Sink
+    {
+        /**
+         * <p>Writes the given content, notifying the callback when the content has been consumed.</p>
+         * <p>The content must not be retained after the callback has been completed;
+         * no further content is read until the callback has been completed.</p>
+         *
+         * @param content the content to write
+         * @param last whether the content is the last of the part
+         * @param callback the callback to complete when the content has been consumed
+         */
+        void write(ByteBuffer content, boolean last, Callback callback);
+
+        /**
+         * <p>Invoked when the parsing fails before the last content of the part has been written.</p>
+         *
+         * @param failure the failure
+         */
+        default void fail(Throwable failure)
+        {
+        }
+    }
+
+    /**
+     * <p>The headers of a part.</p>
+     */
+    public static class Part
+    {
+        private final String _name;
+        private final String _fileName;
+        private final HttpFields _headers;
+
+        private Part(String name, String fileName, HttpFields headers)
+        {
+            _name = name;
+            _fileName = fileName;
+            _headers = headers;
+        }
+
+        /**
+         * @return the name of the part, from the {@code Content-Disposition} header
+         */
+        public String getName()
+        {
+            return _name;
+        }
+
+        /**
+         * @return the file name of the part, from the {@code Content-Disposition} header, or null
+         */
+        public String getFileName()
+        {
+            return _fileName;
+        }
+
+        /**
+         * @return the {@code Content-Type} of the part, or null
+         */
+        public String getContentType()
+        {
+            return _headers.get("Content-Type");
+        }
+
+        /**
+         * @return the headers of the part
+         */
+        public HttpFields getHeaders()
+        {
+            return _headers;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[name=%s,fileName=%s]", getClass().getSimpleName(), hashCode(), _name, _fileName);
+        }
+    }
+
+    /**
+     * <p>A {@link Sink} that stores the part content in a file.</p>
+     *
+     * @see #newPathSink()
+     */
+    public class PathSink implements Sink
+    {
+        private final SeekableByteChannel _channel;
+        private Path _path;
+        private long _size;
+        private boolean _temporary = true;
+
+        private PathSink(Path path) throws IOException
+        {
+            _path = path;
+            _channel = Files.newByteChannel(path, StandardOpenOption.WRITE);
+        }
+
+        /**
+         * @return the path of the file that stores the part content
+         */
+        public Path getPath()
+        {
+            return _path;
+        }
+
+        /**
+         * @return the number of bytes written to the file
+         */
+        public long getSize()
+        {
+            return _size;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            try
+            {
+                while (content.hasRemaining())
+                {
+                    _size += _channel.write(content);
+                }
+                if (last)
+                    _channel.close();
+                callback.succeeded();
+            }
+            catch (Throwable x)
+            {
+                callback.failed(x);
+            }
+        }
+
+        @Override
+        public void fail(Throwable failure)
+        {
+            cleanUp();
+        }
+
+        /**
+         * <p>Moves the file to the given path.</p>
+         * <p>The moved file is not deleted when the request completes.</p>
+         *
+         * @param target the path to move the file to
+         * @return the target path
+         * @throws IOException if the file cannot be moved
+         */
+        public Path moveTo(Path target) throws IOException
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                IO.close(_channel);
+                Files.move(_path, target, StandardCopyOption.REPLACE_EXISTING);
+                _path = target;
+                _temporary = false;
+                _pathSinks.remove(this);
+                return target;
+            }
+        }
+
+        private void cleanUp()
+        {
+            IO.close(_channel);
+            try
+            {
+                if (_temporary)
+                    Files.deleteIfExists(_path);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Could not delete {}", _path, x);
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,size=%d]", getClass().getSimpleName(), hashCode(), _path, _size);
+        }
+    }
+
+    private class Parser extends IteratingCallback implements ReadListener, MultiPartParser.Handler
+    {
+        private final AtomicBoolean _notified = new AtomicBoolean();
+        private final Listener _listener;
+        private final MultiPartParser _multiPartParser;
+        private final byte[] _data;
+        private ServletInputStream _input;
+        private ByteBuffer _buffer = BufferUtil.EMPTY_BUFFER;
+        private volatile Throwable _failure;
+        private boolean _writing;
+        private int _parts;
+        private long _contentSize;
+        private long _partSize;
+        private String _contentDisposition;
+        private HttpFields.Mutable _headers;
+        private Part _part;
+        private Sink _sink;
+
+        private Parser(Listener listener)
+        {
+            _listener = listener;
+            _multiPartParser = new MultiPartParser(this, _boundary);
+            _data = new byte[getBufferSize()];
+        }
+
+        private void setInput(ServletInputStream input)
+        {
+            _input = input;
+        }
+
+        @Override
+        public void onDataAvailable()
+        {
+            iterate();
+        }
+
+        @Override
+        public void onAllDataRead()
+        {
+            iterate();
+        }
+
+        @Override
+        public void onError(Throwable failure)
+        {
+            _failure = failure;
+            iterate();
+        }
+
+        @Override
+        protected Action process() throws Throwable
+        {
+            while (true)
+            {
+                Throwable failure = _failure;
+                if (failure != null)
+                    throw failure;
+
+                if (_buffer.hasRemaining())
+                {
+                    _multiPartParser.parse(_buffer, false);
+                    if (_writing)
+                    {
+                        // Wait for the sink to complete the callback,
+                        // without touching the buffer it is writing.
+                        _writing = false;
+                        return Action.SCHEDULED;
+                    }
+                    continue;
+                }
+
+                if (!_input.isReady())
+                    return Action.IDLE;
+
+                int read = _input.read(_data);
+                if (read < 0)
+                {
+                    _multiPartParser.parse(BufferUtil.EMPTY_BUFFER, true);
+                    MultiPartParser.State state = _multiPartParser.getState();
+                    if (state == MultiPartParser.State.END)
+                        return Action.SUCCEEDED;
+                    if (state == MultiPartParser.State.PREAMBLE)
+                        throw new IOException("Missing initial multi part boundary");
+                    throw new IOException("Incomplete Multipart");
+                }
+                _buffer = ByteBuffer.wrap(_data, 0, read);
+            }
+        }
+
+        @Override
+        public void startPart()
+        {
+            _contentDisposition = null;
+            _headers = HttpFields.build();
+            _part = null;
+            _sink = null;
+            _partSize = 0;
+            ++_parts;
+            if (_maxParts >= 0 && _parts > _maxParts)
+                throw new IllegalStateException(String.format("Form with too many parts [%d > %d]", _parts, _maxParts));
+        }
+
+        @Override
+        public void parsedField(String name, String value)
+        {
+            _headers.add(name, value);
+            if (name.equalsIgnoreCase("content-disposition"))
+                _contentDisposition = value;
+        }
+
+        @Override
+        public boolean headerComplete()
+        {
+            if (_contentDisposition == null)
+                throw new IllegalStateException("Missing content-disposition");
+
+            ContentDisposition disposition = ContentDisposition.parse(_contentDisposition);
+            String name = disposition.getName();
+            String fileName = disposition.getFileName();
+            if (!disposition.isFormData())
+                throw new IllegalStateException("Part not form-data");
+            if (name == null)
+                throw new IllegalStateException("No name in part");
+
+            _part = new Part(name, fileName, _headers.asImmutable());
+            if (LOG.isDebugEnabled())
+                LOG.debug("Parsed {} on {}", _part, MultiPartFormStream.this);
+            try
+            {
+                _sink = _listener.onPart(_part);
+            }
+            catch (IOException x)
+            {
+                throw new IllegalStateException(x);
+            }
+            return false;
+        }
+
+        @Override
+        public boolean content(ByteBuffer buffer, boolean last)
+        {
+            int length = buffer.remaining();
+            _partSize += length;
+            _contentSize += length;
+            if (_maxPartSize >= 0 && _partSize > _maxPartSize)
+                throw new IllegalStateException("Multipart Mime part " + _part.getName() + " exceeds max part size " + _maxPartSize);
+            if (_maxContentSize >= 0 && _contentSize > _maxContentSize)
+                throw new IllegalStateException("Request exceeds maxContentSize (" + _maxContentSize + ")");
+
+            Sink sink = _sink;
+            if (sink == null || (length == 0 && !last))
+                return false;
+
+            if (last)
+                _sink = null;
+            _writing = true;
+            sink.write(buffer, last, this);
+            // Stop parsing until the sink completes the callback.
+            return true;
+        }
+
+        private void abort(Throwable failure)
+        {
+            close();
+            notifyFailure(failure);
+        }
+
+        @Override
+        protected void onCompleteSuccess()
+        {
+            if (_notified.compareAndSet(false, true))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Parsed {} parts on {}", _parts, MultiPartFormStream.this);
+                try
+                {
+                    _listener.onComplete();
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", _listener, x);
+                }
+            }
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable failure)
+        {
+            notifyFailure(failure);
+        }
+
+        private void notifyFailure(Throwable failure)
+        {
+            if (!_notified.compareAndSet(false, true))
+                return;
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failed parsing on {}", MultiPartFormStream.this, failure);
+
+            Sink sink = _sink;
+            _sink = null;
+            if (sink != null)
+            {
+                try
+                {
+                    sink.fail(failure);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying sink {}", sink, x);
+                }
+            }
+
+            try
+            {
+                _listener.onFailure(failure);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", _listener, x);
+            }
+        }
+
+        @Override
+        public InvocationType getInvocationType()
+        {
+            return InvocationType.BLOCKING;
+        }
+    }
+}
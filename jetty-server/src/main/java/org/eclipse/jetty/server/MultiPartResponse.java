@@ -0,0 +1,305 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.ByteArrayInputStream;
+import java.io.FilterOutputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.List;
+import java.util.concurrent.ThreadLocalRandom;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.MultiPartOutputStream;
+
+/**
+ * <p>A multipart response, by default with the {@code multipart/mixed} content type,
+ * made of parts whose content is only opened when the part is written.</p>
+ * <p>Example usage:</p>
+ * <pre>
+ * MultiPartResponse multiPart = new MultiPartResponse();
+ * multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "application/json"), json.getBytes(UTF_8));
+ * multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "image/png"), Path.of("/tmp/img.png"));
+ * multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "text/csv"), () -&gt; exportAsStream());
+ * multiPart.writeTo(response);
+ * </pre>
+ * <p>The {@code Content-Length} of the response is set only if the length of all the
+ * parts is known, otherwise the response is sent with chunked encoding.</p>
+ * <p>A part may specify a {@code Content-Transfer-Encoding} header: the {@code 7bit},
+ * {@code 8bit} and {@code binary} values write the content as is, while the {@code base64}
+ * value encodes the content while it is written.</p>
+ */
+public class MultiPartResponse
+{
+    private static final String CONTENT_TRANSFER_ENCODING = "Content-Transfer-Encoding";
+    private static final byte[] CRLF = {'\r', '\n'};
+    private static final byte[] DASHDASH = {'-', '-'};
+
+    private final List<Part> _parts = new ArrayList<>();
+    private final String _mimeType;
+    private final String _boundary;
+
+    public MultiPartResponse()
+    {
+        this(MultiPartOutputStream.MULTIPART_MIXED, "jetty" + Long.toString(ThreadLocalRandom.current().nextLong() & Long.MAX_VALUE, 36));
+    }
+
+    /**
+     * @param mimeType the multipart mime type, such as {@code multipart/mixed}
+     * @param boundary the boundary between the parts
+     */
+    public MultiPartResponse(String mimeType, String boundary)
+    {
+        _mimeType = mimeType;
+        _boundary = boundary;
+    }
+
+    public String getBoundary()
+    {
+        return _boundary;
+    }
+
+    /**
+     * @return the content type of the response, with the boundary parameter
+     */
+    public String getContentType()
+    {
+        return _mimeType + "; boundary=" + _boundary;
+    }
+
+    /**
+     * @param headers the part headers
+     * @param content the part content
+     */
+    public void addPart(HttpFields headers, byte[] content)
+    {
+        addPart(headers, content.length, () -> new ByteArrayInputStream(content));
+    }
+
+    /**
+     * <p>Adds a part whose content is the given file, opened when the part is written.</p>
+     *
+     * @param headers the part headers
+     * @param path the file of the part content
+     * @throws IOException if the size of the file cannot be read
+     */
+    public void addPart(HttpFields headers, Path path) throws IOException
+    {
+        addPart(headers, Files.size(path), () -> Files.newInputStream(path));
+    }
+
+    /**
+     * <p>Adds a part of unknown length, whose content is opened when the part is written.</p>
+     *
+     * @param headers the part headers
+     * @param content the part content
+     */
+    public void addPart(HttpFields headers, Content content)
+    {
+        addPart(headers, -1, content);
+    }
+
+    /**
+     * <p>Adds a part whose content is opened when the part is written.</p>
+     *
+     * @param headers the part headers
+     * @param length the length of the part content, or -1 if unknown
+     * @param content the part content
+     */
+    public void addPart(HttpFields headers, long length, Content content)
+    {
+        _parts.add(new Part(headers, length, content));
+    }
+
+    /**
+     * @return the length of the multipart content, or -1 if the length of a part is unknown
+     */
+    public long getLength()
+    {
+        if (_parts.isEmpty())
+            return DASHDASH.length + _boundary.length() + DASHDASH.length + CRLF.length;
+
+        long length = 0;
+        for (int i = 0; i < _parts.size(); ++i)
+        {
+            long partLength = _parts.get(i).getLength();
+            if (partLength < 0)
+                return -1;
+            if (i > 0)
+                length += CRLF.length;
+            length += DASHDASH.length + _boundary.length() + CRLF.length + partLength;
+        }
+        return length + CRLF.length + DASHDASH.length + _boundary.length() + DASHDASH.length + CRLF.length;
+    }
+
+    /**
+     * <p>Sets the content type and, if known, the content length of the given response,
+     * then writes the parts to the response output stream.</p>
+     *
+     * @param response the response to write to
+     * @throws IOException if the parts cannot be written
+     */
+    public void writeTo(HttpServletResponse response) throws IOException
+    {
+        response.setContentType(getContentType());
+        long length = getLength();
+        if (length >= 0)
+            response.setContentLengthLong(length);
+        writeTo(response.getOutputStream());
+    }
+
+    /**
+     * <p>Writes the parts to the given output stream, which is not closed.</p>
+     *
+     * @param out the output stream to write to
+     * @throws IOException if the parts cannot be written
+     */
+    public void writeTo(OutputStream out) throws IOException
+    {
+        byte[] boundary = _boundary.getBytes(StandardCharsets.ISO_8859_1);
+        for (int i = 0; i < _parts.size(); ++i)
+        {
+            if (i > 0)
+                out.write(CRLF);
+            out.write(DASHDASH);
+            out.write(boundary);
+            out.write(CRLF);
+            _parts.get(i).writeTo(out);
+        }
+        if (!_parts.isEmpty())
+            out.write(CRLF);
+        out.write(DASHDASH);
+        out.write(boundary);
+        out.write(DASHDASH);
+        out.write(CRLF);
+        out.flush();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,parts=%d}", getClass().getSimpleName(), hashCode(), getContentType(), _parts.size());
+    }
+
+    /**
+     * <p>The content of a part, opened when the part is written.</p>
+     */
+    @FunctionalInterface
+    public interface Content
+    {
+        /**
+         * @return a stream of the part content, closed once the part is written
+         * @throws IOException if the content cannot be opened
+         */
+        InputStream open() throws IOException;
+    }
+
+    private static class Part
+    {
+        private final byte[] _headers;
+        private final long _length;
+        private final Content _content;
+        private final boolean _base64;
+
+        private Part(HttpFields headers, long length, Content content)
+        {
+            _length = length;
+            _content = content;
+            _base64 = isBase64(headers == null ? null : headers.get(CONTENT_TRANSFER_ENCODING));
+            StringBuilder builder = new StringBuilder();
+            if (headers != null)
+            {
+                for (HttpField field : headers)
+                {
+                    builder.append(field.getName()).append(": ");
+                    if (field.getValue() != null)
+                        builder.append(field.getValue());
+                    builder.append("\r\n");
+                }
+            }
+            builder.append("\r\n");
+            _headers = builder.toString().getBytes(StandardCharsets.ISO_8859_1);
+        }
+
+        private static boolean isBase64(String transferEncoding)
+        {
+            if (transferEncoding == null)
+                return false;
+            switch (transferEncoding.trim().toLowerCase())
+            {
+                case "7bit":
+                case "8bit":
+                case "binary":
+                    return false;
+                case "base64":
+                    return true;
+                default:
+                    throw new IllegalArgumentException("Unsupported " + CONTENT_TRANSFER_ENCODING + ": " + transferEncoding);
+            }
+        }
+
+        private long getLength()
+        {
+            if (_length < 0)
+                return -1;
+            if (!_base64 || _length == 0)
+                return _headers.length + _length;
+            // The MIME encoding has lines of 76 characters, for 57 bytes, separated by CRLF.
+            long lines = (_length + 56) / 57;
+            return _headers.length + 4 * ((_length + 2) / 3) + CRLF.length * (lines - 1);
+        }
+
+        private void writeTo(OutputStream out) throws IOException
+        {
+            out.write(_headers);
+            try (InputStream input = _content.open())
+            {
+                if (_base64)
+                {
+                    // Closing the encoder writes the last bytes, but must not close the response stream.
+                    try (OutputStream encoder = Base64.getMimeEncoder().wrap(new FilterOutputStream(out)
+                    {
+                        @Override
+                        public void write(byte[] b, int off, int len) throws IOException
+                        {
+                            out.write(b, off, len);
+                        }
+
+                        @Override
+                        public void close() throws IOException
+                        {
+                            flush();
+                        }
+                    }))
+                    {
+                        IO.copy(input, encoder);
+                    }
+                }
+                else
+                {
+                    IO.copy(input, out);
+                }
+            }
+        }
+    }
+}
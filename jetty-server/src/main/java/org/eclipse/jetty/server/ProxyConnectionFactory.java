@@ -23,6 +23,7 @@ import java.nio.ByteBuffer;
 import java.nio.channels.ReadPendingException;
 import java.nio.channels.WritePendingException;
 import java.nio.charset.StandardCharsets;
+import java.util.Collections;
 import java.util.HashMap;
 import java.util.Map;
 
@@ -640,19 +641,27 @@ public class ProxyConnectionFactory extends DetectorConnectionFactory
                         if (type != ProxyEndPoint.PP2_TYPE_NOOP)
                             proxyEndPoint.putTLV(type, value);
 
-                        if (type == ProxyEndPoint.PP2_TYPE_SSL)
+                        if (type == ProxyEndPoint.PP2_TYPE_SSL && length >= 5)
                         {
+                            // struct pp2_tlv_ssl {
+                            //     uint8_t  client;
+                            //     uint32_t verify;
+                            //     struct pp2_tlv sub_tlv[0];
+                            // };
                             int client = value[0] & 0xFF;
-                            if (client == ProxyEndPoint.PP2_TYPE_SSL_PP2_CLIENT_SSL)
+                            if ((client & ProxyEndPoint.PP2_CLIENT_SSL) == ProxyEndPoint.PP2_CLIENT_SSL)
                             {
                                 int i = 5; // Index of the first sub_tlv, after verify.
-                                while (i < length)
+                                while (i + 3 <= length)
                                 {
                                     int subType = value[i++] & 0xFF;
                                     int subLength = (value[i++] & 0xFF) * 256 + (value[i++] & 0xFF);
+                                    if (i + subLength > length)
+                                        throw new IOException("Proxy v2 bad SSL sub-TLV length");
                                     byte[] subValue = new byte[subLength];
                                     System.arraycopy(value, i, subValue, 0, subLength);
                                     i += subLength;
+                                    proxyEndPoint.putSslTLV(subType, subValue);
                                     if (subType == ProxyEndPoint.PP2_SUBTYPE_SSL_VERSION)
                                     {
                                         String tlsVersion = new String(subValue, StandardCharsets.US_ASCII);
@@ -757,15 +766,35 @@ public class ProxyConnectionFactory extends DetectorConnectionFactory
 
     public static class ProxyEndPoint extends AttributesMap implements EndPoint, EndPoint.Wrapper
     {
-        private static final int PP2_TYPE_NOOP = 0x04;
-        private static final int PP2_TYPE_SSL = 0x20;
-        private static final int PP2_TYPE_SSL_PP2_CLIENT_SSL = 0x01;
-        private static final int PP2_SUBTYPE_SSL_VERSION = 0x21;
+        public static final int PP2_TYPE_ALPN = 0x01;
+        public static final int PP2_TYPE_AUTHORITY = 0x02;
+        public static final int PP2_TYPE_CRC32C = 0x03;
+        public static final int PP2_TYPE_NOOP = 0x04;
+        public static final int PP2_TYPE_UNIQUE_ID = 0x05;
+        public static final int PP2_TYPE_SSL = 0x20;
+        public static final int PP2_SUBTYPE_SSL_VERSION = 0x21;
+        public static final int PP2_SUBTYPE_SSL_CN = 0x22;
+        public static final int PP2_SUBTYPE_SSL_CIPHER = 0x23;
+        public static final int PP2_SUBTYPE_SSL_SIG_ALG = 0x24;
+        public static final int PP2_SUBTYPE_SSL_KEY_ALG = 0x25;
+        public static final int PP2_TYPE_NETNS = 0x30;
+        /**
+         * The first TLV type of the range reserved for application-specific data.
+         */
+        public static final int PP2_TYPE_MIN_CUSTOM = 0xE0;
+        /**
+         * The last TLV type of the range reserved for application-specific data.
+         */
+        public static final int PP2_TYPE_MAX_CUSTOM = 0xEF;
+        public static final int PP2_CLIENT_SSL = 0x01;
+        public static final int PP2_CLIENT_CERT_CONN = 0x02;
+        public static final int PP2_CLIENT_CERT_SESS = 0x04;
 
         private final EndPoint _endPoint;
         private final SocketAddress _local;
         private final SocketAddress _remote;
         private Map<Integer, byte[]> _tlvs;
+        private Map<Integer, byte[]> _sslTLVs;
 
         @Deprecated
         public ProxyEndPoint(EndPoint endPoint, InetSocketAddress remote, InetSocketAddress local)
@@ -809,6 +838,57 @@ public class ProxyConnectionFactory extends DetectorConnectionFactory
             return _tlvs != null ? _tlvs.get(type) : null;
         }
 
+        /**
+         * @return an unmodifiable map of the TLV vectors, keyed by TLV type
+         */
+        public Map<Integer, byte[]> getTLVs()
+        {
+            return _tlvs != null ? Collections.unmodifiableMap(_tlvs) : Collections.emptyMap();
+        }
+
+        private void putSslTLV(int subType, byte[] value)
+        {
+            if (_sslTLVs == null)
+                _sslTLVs = new HashMap<>();
+            _sslTLVs.put(subType, value);
+        }
+
+        /**
+         * <p>Gets a sub-TLV vector of the {@link #PP2_TYPE_SSL} TLV, see section 2.2.5
+         * of the PROXY protocol specification.</p>
+         *
+         * @param subType the sub-TLV type, for example {@link #PP2_SUBTYPE_SSL_CIPHER}
+         * @return the sub-TLV value or null if not present.
+         */
+        public byte[] getSslTLV(int subType)
+        {
+            return _sslTLVs != null ? _sslTLVs.get(subType) : null;
+        }
+
+        /**
+         * @return the {@code client} field of the {@link #PP2_TYPE_SSL} TLV,
+         * a bitmask of {@link #PP2_CLIENT_SSL}, {@link #PP2_CLIENT_CERT_CONN}
+         * and {@link #PP2_CLIENT_CERT_SESS}, or 0 if not present
+         */
+        public int getSslClient()
+        {
+            byte[] ssl = getTLV(PP2_TYPE_SSL);
+            return ssl == null || ssl.length < 1 ? 0 : ssl[0] & 0xFF;
+        }
+
+        /**
+         * @return whether the client presented a certificate that was successfully verified
+         */
+        public boolean isSslClientVerified()
+        {
+            byte[] ssl = getTLV(PP2_TYPE_SSL);
+            if (ssl == null || ssl.length < 5)
+                return false;
+            if ((getSslClient() & (PP2_CLIENT_CERT_CONN | PP2_CLIENT_CERT_SESS)) == 0)
+                return false;
+            return ssl[1] == 0 && ssl[2] == 0 && ssl[3] == 0 && ssl[4] == 0;
+        }
+
         @Override
         public void close(Throwable cause)
         {
@@ -15,7 +15,9 @@ package org.eclipse.jetty.server;
 
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
+import java.nio.charset.StandardCharsets;
 import java.util.HashSet;
+import java.util.Map;
 import java.util.Set;
 import javax.servlet.ServletRequest;
 
@@ -24,7 +26,10 @@ import org.eclipse.jetty.util.Attributes;
 
 /**
  * <p>Customizer that extracts the real local and remote address:port pairs from a {@link ProxyConnectionFactory}
- * and sets them on the request with {@link ServletRequest#setAttribute(String, Object)}.
+ * and sets them on the request with {@link ServletRequest#setAttribute(String, Object)}.</p>
+ * <p>For the PROXY protocol version 2, the standard TLV vectors (such as ALPN, authority,
+ * unique ID and the TLS information) are also available as request attributes, along
+ * with the map of all the TLV vectors, which includes the application-specific ones.</p>
  */
 public class ProxyCustomizer implements HttpConfiguration.Customizer
 {
@@ -48,27 +53,88 @@ public class ProxyCustomizer implements HttpConfiguration.Customizer
      */
     public static final String LOCAL_PORT_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.local.port";
 
+    /**
+     * The ALPN protocol attribute name, as a {@code String}.
+     */
+    public static final String ALPN_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.alpn";
+
+    /**
+     * The authority (typically the TLS SNI host name) attribute name, as a {@code String}.
+     */
+    public static final String AUTHORITY_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.authority";
+
+    /**
+     * The connection unique ID attribute name, as a {@code byte[]}.
+     */
+    public static final String UNIQUE_ID_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.unique_id";
+
+    /**
+     * The TLS protocol version attribute name, as a {@code String}.
+     */
+    public static final String TLS_VERSION_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.version";
+
+    /**
+     * The TLS client certificate Common Name attribute name, as a {@code String}.
+     */
+    public static final String TLS_CN_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.cn";
+
+    /**
+     * The TLS cipher suite attribute name, as a {@code String}.
+     */
+    public static final String TLS_CIPHER_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.cipher";
+
+    /**
+     * The TLS certificate signature algorithm attribute name, as a {@code String}.
+     */
+    public static final String TLS_SIG_ALG_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.sig_alg";
+
+    /**
+     * The TLS certificate key algorithm attribute name, as a {@code String}.
+     */
+    public static final String TLS_KEY_ALG_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.key_alg";
+
+    /**
+     * The TLS client certificate verification attribute name, as a {@code Boolean}.
+     */
+    public static final String TLS_CLIENT_VERIFIED_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tls.client_verified";
+
+    /**
+     * The attribute name of the map of all the TLV vectors, as a {@code Map<Integer, byte[]>}.
+     */
+    public static final String TLVS_ATTRIBUTE_NAME = "org.eclipse.jetty.proxy.tlvs";
+
     @Override
     public void customize(Connector connector, HttpConfiguration channelConfig, Request request)
     {
         EndPoint endPoint = request.getHttpChannel().getEndPoint();
         if (endPoint instanceof ProxyConnectionFactory.ProxyEndPoint)
         {
-            EndPoint underlyingEndpoint = ((ProxyConnectionFactory.ProxyEndPoint)endPoint).unwrap();
-            request.setAttributes(new ProxyAttributes(underlyingEndpoint.getLocalSocketAddress(), underlyingEndpoint.getRemoteSocketAddress(), request.getAttributes()));
+            ProxyConnectionFactory.ProxyEndPoint proxyEndPoint = (ProxyConnectionFactory.ProxyEndPoint)endPoint;
+            EndPoint underlyingEndpoint = proxyEndPoint.unwrap();
+            request.setAttributes(new ProxyAttributes(underlyingEndpoint.getLocalSocketAddress(), underlyingEndpoint.getRemoteSocketAddress(), proxyEndPoint, request.getAttributes()));
         }
     }
 
     private static class ProxyAttributes extends Attributes.Wrapper
     {
+        private static final String[] TLV_ATTRIBUTE_NAMES =
+        {
+            ALPN_ATTRIBUTE_NAME, AUTHORITY_ATTRIBUTE_NAME, UNIQUE_ID_ATTRIBUTE_NAME,
+            TLS_VERSION_ATTRIBUTE_NAME, TLS_CN_ATTRIBUTE_NAME, TLS_CIPHER_ATTRIBUTE_NAME,
+            TLS_SIG_ALG_ATTRIBUTE_NAME, TLS_KEY_ALG_ATTRIBUTE_NAME, TLS_CLIENT_VERIFIED_ATTRIBUTE_NAME,
+            TLVS_ATTRIBUTE_NAME
+        };
+
         private final String _remoteAddress;
         private final String _localAddress;
         private final int _remotePort;
         private final int _localPort;
+        private final ProxyConnectionFactory.ProxyEndPoint _proxyEndPoint;
 
-        private ProxyAttributes(SocketAddress local, SocketAddress remote, Attributes attributes)
+        private ProxyAttributes(SocketAddress local, SocketAddress remote, ProxyConnectionFactory.ProxyEndPoint proxyEndPoint, Attributes attributes)
         {
             super(attributes);
+            _proxyEndPoint = proxyEndPoint;
             InetSocketAddress inetLocal = local instanceof InetSocketAddress ? (InetSocketAddress)local : null;
             InetSocketAddress inetRemote = remote instanceof InetSocketAddress ? (InetSocketAddress)remote : null;
             _localAddress = inetLocal == null ? null : inetLocal.getAddress().getHostAddress();
@@ -90,11 +156,40 @@ public class ProxyCustomizer implements HttpConfiguration.Customizer
                     return _localAddress;
                 case LOCAL_PORT_ATTRIBUTE_NAME:
                     return _localPort;
+                case ALPN_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_TYPE_ALPN));
+                case AUTHORITY_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_TYPE_AUTHORITY));
+                case UNIQUE_ID_ATTRIBUTE_NAME:
+                    return _proxyEndPoint.getTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_TYPE_UNIQUE_ID);
+                case TLS_VERSION_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getSslTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_SUBTYPE_SSL_VERSION));
+                case TLS_CN_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getSslTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_SUBTYPE_SSL_CN));
+                case TLS_CIPHER_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getSslTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_SUBTYPE_SSL_CIPHER));
+                case TLS_SIG_ALG_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getSslTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_SUBTYPE_SSL_SIG_ALG));
+                case TLS_KEY_ALG_ATTRIBUTE_NAME:
+                    return asString(_proxyEndPoint.getSslTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_SUBTYPE_SSL_KEY_ALG));
+                case TLS_CLIENT_VERIFIED_ATTRIBUTE_NAME:
+                    return _proxyEndPoint.getTLV(ProxyConnectionFactory.ProxyEndPoint.PP2_TYPE_SSL) == null ? null : _proxyEndPoint.isSslClientVerified();
+                case TLVS_ATTRIBUTE_NAME:
+                {
+                    Map<Integer, byte[]> tlvs = _proxyEndPoint.getTLVs();
+                    return tlvs.isEmpty() ? null : tlvs;
+                }
                 default:
                     return super.getAttribute(name);
             }
         }
 
+        private static String asString(byte[] value)
+        {
+            // UTF-8 is a superset of US-ASCII used by most TLVs.
+            return value == null ? null : new String(value, StandardCharsets.UTF_8);
+        }
+
         @Override
         public Set<String> getAttributeNameSet()
         {
@@ -108,6 +203,11 @@ public class ProxyCustomizer implements HttpConfiguration.Customizer
                 names.add(LOCAL_ADDRESS_ATTRIBUTE_NAME);
             names.add(REMOTE_PORT_ATTRIBUTE_NAME);
             names.add(LOCAL_PORT_ATTRIBUTE_NAME);
+            for (String name : TLV_ATTRIBUTE_NAMES)
+            {
+                if (getAttribute(name) != null)
+                    names.add(name);
+            }
             return names;
         }
     }
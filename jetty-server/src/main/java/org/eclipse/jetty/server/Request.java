@@ -15,6 +15,7 @@ package org.eclipse.jetty.server;
 
 import java.io.BufferedReader;
 import java.io.ByteArrayOutputStream;
+import java.io.EOFException;
 import java.io.File;
 import java.io.IOException;
 import java.io.InputStream;
@@ -37,6 +38,7 @@ import java.util.List;
 import java.util.Locale;
 import java.util.Map;
 import java.util.Objects;
+import java.util.concurrent.CompletableFuture;
 import java.util.stream.Collectors;
 import javax.servlet.AsyncContext;
 import javax.servlet.AsyncListener;
@@ -80,6 +82,7 @@ import org.eclipse.jetty.http.HttpURI;
 import org.eclipse.jetty.http.HttpVersion;
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.http.UriCanonicalizer;
 import org.eclipse.jetty.http.UriCompliance;
 import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.RuntimeIOException;
@@ -92,9 +95,11 @@ import org.eclipse.jetty.util.AttributesMap;
 import org.eclipse.jetty.util.HostPort;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.MultiMap;
+import org.eclipse.jetty.util.ParameterParser;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.UrlEncoded;
+import org.eclipse.jetty.util.thread.AutoLock;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -201,6 +206,10 @@ public class Request implements HttpServletRequest
     private MetaData.Request _metaData;
     private HttpFields _httpFields;
     private HttpFields _trailers;
+    private final AutoLock _trailersLock = new AutoLock();
+    private CompletableFuture<HttpFields> _trailersFuture;
+    private boolean _contentComplete;
+    private Throwable _contentFailure;
     private HttpURI _uri;
     private String _method;
     private String _pathInContext;
@@ -279,6 +288,55 @@ public class Request implements HttpServletRequest
         return _trailers;
     }
 
+    /**
+     * <p>Returns a future completed when the request content has been fully received.</p>
+     * <p>The future is completed with the request trailers, or with {@link HttpFields#EMPTY}
+     * if the request has no trailers, or completed exceptionally if the request
+     * completes before its content has been fully received.</p>
+     *
+     * @return a future completed with the request trailers
+     */
+    public CompletableFuture<HttpFields> getTrailersFuture()
+    {
+        CompletableFuture<HttpFields> future;
+        boolean complete;
+        Throwable failure;
+        try (AutoLock ignored = _trailersLock.lock())
+        {
+            if (_trailersFuture != null)
+                return _trailersFuture;
+            future = _trailersFuture = new CompletableFuture<>();
+            complete = _contentComplete;
+            failure = _contentFailure;
+        }
+        if (complete)
+            completeTrailersFuture(future, failure);
+        return future;
+    }
+
+    void onContentComplete(Throwable failure)
+    {
+        CompletableFuture<HttpFields> future;
+        try (AutoLock ignored = _trailersLock.lock())
+        {
+            if (_contentComplete)
+                return;
+            _contentComplete = true;
+            _contentFailure = failure;
+            future = _trailersFuture;
+        }
+        if (future != null)
+            completeTrailersFuture(future, failure);
+    }
+
+    private void completeTrailersFuture(CompletableFuture<HttpFields> future, Throwable failure)
+    {
+        if (failure != null)
+            future.completeExceptionally(failure);
+        else
+            future.complete(_trailers == null ? HttpFields.EMPTY : _trailers);
+    }
+
     public HttpInput getHttpInput()
     {
         return _input;
@@ -465,6 +523,11 @@ public class Request implements HttpServletRequest
                 {
                     extractContentParameters();
                 }
+                catch (ParameterParser.ViolationException e)
+                {
+                    LOG.warn(e.toString());
+                    throw new BadMessageException("Unable to parse form content: " + e.getMessage(), e);
+                }
                 catch (IllegalStateException | IllegalArgumentException e)
                 {
                     LOG.warn(e.toString());
@@ -505,7 +568,12 @@ public class Request implements HttpServletRequest
             try
             {
                 _queryParameters = new MultiMap<>();
-                UrlEncoded.decodeTo(_uri.getQuery(), _queryParameters, _queryEncoding);
+                newParameterParser().parse(_uri.getQuery(), _queryEncoding, _queryParameters);
+            }
+            catch (ParameterParser.ViolationException e)
+            {
+                _queryParameters = BAD_PARAMS;
+                throw new BadMessageException("Unable to parse URI query: " + e.getMessage(), e);
             }
             catch (IllegalStateException | IllegalArgumentException e)
             {
@@ -573,30 +641,20 @@ public class Request implements HttpServletRequest
     {
         try
         {
-            int maxFormContentSize = ContextHandler.DEFAULT_MAX_FORM_CONTENT_SIZE;
-            int maxFormKeys = ContextHandler.DEFAULT_MAX_FORM_KEYS;
-
-            if (_context != null)
-            {
-                ContextHandler contextHandler = _context.getContextHandler();
-                maxFormContentSize = contextHandler.getMaxFormContentSize();
-                maxFormKeys = contextHandler.getMaxFormKeys();
-            }
-            else
-            {
-                maxFormContentSize = lookupServerAttribute(ContextHandler.MAX_FORM_CONTENT_SIZE_KEY, maxFormContentSize);
-                maxFormKeys = lookupServerAttribute(ContextHandler.MAX_FORM_KEYS_KEY, maxFormKeys);
-            }
+            ParameterParser parser = newParameterParser();
 
             int contentLength = getContentLength();
-            if (maxFormContentSize >= 0 && contentLength > maxFormContentSize)
-                throw new IllegalStateException("Form is larger than max length " + maxFormContentSize);
+            int maxFormContentSize = parser.getMaxLength();
+            // Fail early, unless a listener may tolerate the violation while parsing.
+            if (maxFormContentSize >= 0 && contentLength > maxFormContentSize && parser.getViolationListener() == null)
+                throw new ParameterParser.ViolationException(ParameterParser.Violation.CONTENT_TOO_LARGE, "Form is larger than max length " + maxFormContentSize);
 
             InputStream in = getInputStream();
             if (_input.isAsync())
                 throw new IllegalStateException("Cannot extract parameters with async IO");
 
-            UrlEncoded.decodeTo(in, params, getCharacterEncoding(), maxFormContentSize, maxFormKeys);
+            String encoding = getCharacterEncoding();
+            parser.parse(in, encoding == null ? null : Charset.forName(encoding), params);
         }
         catch (IOException e)
         {
@@ -607,6 +665,26 @@ public class Request implements HttpServletRequest
         }
     }
 
+    /**
+     * <p>Returns a new {@link ParameterParser} to parse the query and form parameters of this request.</p>
+     * <p>The parser is configured by the {@link ContextHandler} of this request, see
+     * {@link ContextHandler#newParameterParser()}, or if there is no context by the
+     * form limits {@link Server} attributes.</p>
+     *
+     * @return a new parameter parser
+     */
+    public ParameterParser newParameterParser()
+    {
+        if (_context != null)
+            return _context.getContextHandler().newParameterParser();
+
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxKeys(lookupServerAttribute(ContextHandler.MAX_FORM_KEYS_KEY, ContextHandler.DEFAULT_MAX_FORM_KEYS));
+        parser.setMaxLength(lookupServerAttribute(ContextHandler.MAX_FORM_CONTENT_SIZE_KEY, ContextHandler.DEFAULT_MAX_FORM_CONTENT_SIZE));
+        parser.setMaxValueLength(lookupServerAttribute(ContextHandler.MAX_FORM_VALUE_LENGTH_KEY, -1));
+        return parser;
+    }
+
     private int lookupServerAttribute(String key, int dftValue)
     {
         Object attribute = _channel.getServer().getAttribute(key);
@@ -1441,6 +1519,8 @@ public class Request implements HttpServletRequest
      */
     public void onCompleted()
     {
+        onContentComplete(new EOFException("Request content not fully received"));
+
         HttpChannel httpChannel = getHttpChannel();
         // httpChannel can be null in some scenarios
         // it's not possible to use requestlog in those scenarios anyway.
@@ -1754,15 +1834,11 @@ public class Request implements HttpServletRequest
                 if (httpConfiguration != null)
                 {
                     HttpCompliance httpCompliance = httpConfiguration.getHttpCompliance();
-                    if (httpCompliance.allows(MISMATCHED_AUTHORITY))
-                    {
-                        if (httpChannel instanceof ComplianceViolation.Listener)
-                            ((ComplianceViolation.Listener)httpChannel).onComplianceViolation(httpCompliance, MISMATCHED_AUTHORITY, _uri.toString());
-                    }
-                    else
-                    {
+                    boolean allowed = httpCompliance.allows(MISMATCHED_AUTHORITY);
+                    if (httpChannel instanceof ComplianceViolation.Listener)
+                        ((ComplianceViolation.Listener)httpChannel).onComplianceViolation(new ComplianceViolation.Event(httpCompliance, MISMATCHED_AUTHORITY, _uri.toString(), allowed));
+                    if (!allowed)
                         throw new BadMessageException(400, "Mismatched Authority");
-                    }
                 }
             }
         }
@@ -1800,7 +1876,11 @@ public class Request implements HttpServletRequest
             path = _uri.isAbsolute() ? "/" : null;
         else if (encoded.startsWith("/"))
         {
-            path = (encoded.length() == 1) ? "/" : _uri.getDecodedPath();
+            UriCanonicalizer.CanonicalPath canonical = canonicalizePath(encoded);
+            if (canonical != null)
+                path = canonical.getDecodedPath();
+            else
+                path = (encoded.length() == 1) ? "/" : _uri.getDecodedPath();
         }
         else if ("*".equals(encoded) || HttpMethod.CONNECT.is(getMethod()))
         {
@@ -1819,6 +1899,24 @@ public class Request implements HttpServletRequest
         _pathInContext = path;
     }
 
+    /**
+     * <p>Canonicalizes an encoded path with the {@link HttpConfiguration#getUriCanonicalizer() URI canonicalizer}
+     * of this request, notifying the violations to the channel if it is a {@link ComplianceViolation.Listener}.</p>
+     *
+     * @param path the encoded absolute path, without query
+     * @return the canonical path, or null if no URI canonicalizer is configured
+     * @throws BadMessageException if the path is rejected by the URI canonicalizer
+     */
+    public UriCanonicalizer.CanonicalPath canonicalizePath(String path)
+    {
+        HttpConfiguration httpConfiguration = _channel == null ? null : _channel.getHttpConfiguration();
+        UriCanonicalizer canonicalizer = httpConfiguration == null ? null : httpConfiguration.getUriCanonicalizer();
+        if (canonicalizer == null)
+            return null;
+        ComplianceViolation.Listener listener = _channel instanceof ComplianceViolation.Listener ? (ComplianceViolation.Listener)_channel : null;
+        return canonicalizer.canonicalize(path, listener);
+    }
+
     public org.eclipse.jetty.http.MetaData.Request getMetaData()
     {
         return _metaData;
@@ -1857,6 +1955,12 @@ public class Request implements HttpServletRequest
         _metaData = null;
         _httpFields = null;
         _trailers = null;
+        try (AutoLock ignored = _trailersLock.lock())
+        {
+            _trailersFuture = null;
+            _contentComplete = false;
+            _contentFailure = null;
+        }
         _uri = null;
         _method = null;
         _pathInContext = null;
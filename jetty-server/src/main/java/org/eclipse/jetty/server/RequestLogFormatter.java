@@ -0,0 +1,198 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.time.Instant;
+import java.time.ZoneId;
+import java.time.format.DateTimeFormatter;
+import java.util.Locale;
+import java.util.Map;
+
+/**
+ * <p>Serializes the typed fields produced by {@link StructuredRequestLog} into a log entry.</p>
+ * <p>Field values are either {@code null}, a {@link Number}, a {@link Boolean}
+ * or an object whose {@link Object#toString()} is logged.</p>
+ *
+ * @see StructuredRequestLog
+ */
+@FunctionalInterface
+public interface RequestLogFormatter
+{
+    /**
+     * @param fields the ordered map of field names to field values
+     * @return the formatted log entry
+     */
+    String format(Map<String, Object> fields);
+
+    /**
+     * <p>Formats fields as a single line JSON object, omitting {@code null} values.</p>
+     */
+    class Json implements RequestLogFormatter
+    {
+        @Override
+        public String format(Map<String, Object> fields)
+        {
+            StringBuilder builder = new StringBuilder(256);
+            builder.append('{');
+            boolean first = true;
+            for (Map.Entry<String, Object> entry : fields.entrySet())
+            {
+                Object value = entry.getValue();
+                if (value == null)
+                    continue;
+                if (!first)
+                    builder.append(',');
+                first = false;
+                quote(builder, entry.getKey());
+                builder.append(':');
+                if (value instanceof Number || value instanceof Boolean)
+                    builder.append(value);
+                else
+                    quote(builder, value.toString());
+            }
+            builder.append('}');
+            return builder.toString();
+        }
+
+        private static void quote(StringBuilder builder, String value)
+        {
+            builder.append('"');
+            for (int i = 0; i < value.length(); ++i)
+            {
+                char c = value.charAt(i);
+                switch (c)
+                {
+                    case '"':
+                        builder.append("\\\"");
+                        break;
+                    case '\\':
+                        builder.append("\\\\");
+                        break;
+                    case '\n':
+                        builder.append("\\n");
+                        break;
+                    case '\r':
+                        builder.append("\\r");
+                        break;
+                    case '\t':
+                        builder.append("\\t");
+                        break;
+                    default:
+                        if (c < 0x20)
+                            builder.append(String.format("\\u%04x", (int)c));
+                        else
+                            builder.append(c);
+                        break;
+                }
+            }
+            builder.append('"');
+        }
+    }
+
+    /**
+     * <p>Formats fields as space separated {@code key=value} pairs,
+     * quoting values that contain spaces, quotes or equal signs.</p>
+     */
+    class Logfmt implements RequestLogFormatter
+    {
+        @Override
+        public String format(Map<String, Object> fields)
+        {
+            StringBuilder builder = new StringBuilder(256);
+            for (Map.Entry<String, Object> entry : fields.entrySet())
+            {
+                Object value = entry.getValue();
+                if (value == null)
+                    continue;
+                if (builder.length() > 0)
+                    builder.append(' ');
+                builder.append(entry.getKey()).append('=');
+                String string = value.toString();
+                if (string.isEmpty() || string.chars().anyMatch(c -> c <= ' ' || c == '"' || c == '='))
+                {
+                    builder.append('"');
+                    for (int i = 0; i < string.length(); ++i)
+                    {
+                        char c = string.charAt(i);
+                        if (c == '"' || c == '\\')
+                            builder.append('\\');
+                        builder.append(c < ' ' ? ' ' : c);
+                    }
+                    builder.append('"');
+                }
+                else
+                {
+                    builder.append(string);
+                }
+            }
+            return builder.toString();
+        }
+    }
+
+    /**
+     * <p>Formats the standard fields in the
+     * <a href="https://httpd.apache.org/docs/current/logs.html#common">Common Log Format</a>,
+     * equivalent to {@link CustomRequestLog#NCSA_FORMAT}; other fields are ignored.</p>
+     */
+    class CommonLogFormat implements RequestLogFormatter
+    {
+        private final DateTimeFormatter _dateFormatter;
+
+        public CommonLogFormat()
+        {
+            this(ZoneId.of("GMT"));
+        }
+
+        public CommonLogFormat(ZoneId zoneId)
+        {
+            _dateFormatter = DateTimeFormatter.ofPattern("dd/MMM/yyyy:HH:mm:ss Z", Locale.US).withZone(zoneId);
+        }
+
+        @Override
+        public String format(Map<String, Object> fields)
+        {
+            StringBuilder builder = new StringBuilder(128);
+            append(builder, fields.get(StructuredRequestLog.CLIENT_ADDRESS));
+            builder.append(" - ");
+            append(builder, fields.get(StructuredRequestLog.USER));
+            builder.append(" [");
+            Object timestamp = fields.get(StructuredRequestLog.TIMESTAMP);
+            if (timestamp instanceof Instant)
+                builder.append(_dateFormatter.format((Instant)timestamp));
+            else
+                append(builder, timestamp);
+            builder.append("] \"");
+            append(builder, fields.get(StructuredRequestLog.METHOD));
+            builder.append(' ');
+            append(builder, fields.get(StructuredRequestLog.URI));
+            builder.append(' ');
+            append(builder, fields.get(StructuredRequestLog.PROTOCOL));
+            builder.append("\" ");
+            append(builder, fields.get(StructuredRequestLog.STATUS));
+            builder.append(' ');
+            Object sent = fields.get(StructuredRequestLog.BYTES_SENT);
+            append(builder, Long.valueOf(0).equals(sent) ? null : sent);
+            return builder.toString();
+        }
+
+        private static void append(StringBuilder builder, Object value)
+        {
+            String string = value == null ? null : value.toString();
+            if (string == null || string.isEmpty())
+                builder.append('-');
+            else
+                builder.append(string);
+        }
+    }
+}
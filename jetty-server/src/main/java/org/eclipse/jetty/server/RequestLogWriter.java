@@ -17,6 +17,7 @@ import java.io.IOException;
 import java.io.OutputStream;
 import java.io.OutputStreamWriter;
 import java.io.Writer;
+import java.util.List;
 import java.util.TimeZone;
 
 import org.eclipse.jetty.util.RolloverFileOutputStream;
@@ -182,6 +183,27 @@ public class RequestLogWriter extends AbstractLifeCycle implements RequestLog.Wr
         }
     }
 
+    /**
+     * <p>Writes the given request log entries, flushing the log file only once.</p>
+     *
+     * @param requestEntries the request log entries to write
+     * @throws IOException if the entries cannot be written
+     */
+    public void write(List<String> requestEntries) throws IOException
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (_writer == null)
+                return;
+            for (String requestEntry : requestEntries)
+            {
+                _writer.write(requestEntry);
+                _writer.write(System.lineSeparator());
+            }
+            _writer.flush();
+        }
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -13,18 +13,22 @@
 
 package org.eclipse.jetty.server;
 
+import java.io.File;
 import java.io.FileNotFoundException;
 import java.io.IOException;
 import java.io.InputStream;
 import java.io.OutputStream;
 import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
 import java.nio.charset.StandardCharsets;
 import java.nio.file.InvalidPathException;
+import java.nio.file.StandardOpenOption;
 import java.util.Collection;
 import java.util.Enumeration;
 import java.util.List;
 import java.util.Map;
 import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.atomic.LongAdder;
 import java.util.function.Supplier;
 import javax.servlet.AsyncContext;
 import javax.servlet.RequestDispatcher;
@@ -34,6 +38,7 @@ import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.CompressedContentFormat;
+import org.eclipse.jetty.http.ContentDisposition;
 import org.eclipse.jetty.http.DateParser;
 import org.eclipse.jetty.http.HttpContent;
 import org.eclipse.jetty.http.HttpField;
@@ -50,6 +55,7 @@ import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.MultiPartOutputStream;
+import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.resource.Resource;
 import org.slf4j.Logger;
@@ -81,6 +87,10 @@ public class ResourceService
     private boolean _etags = false;
     private HttpField _cacheControl;
     private List<String> _gzipEquivalentFileExtensions;
+    private List<String> _attachmentFileExtensions;
+    private long _sendFileThreshold = -1;
+    private final LongAdder _sendFileResponses = new LongAdder();
+    private final LongAdder _sendFileBytes = new LongAdder();
 
     public HttpContent.ContentFactory getContentFactory()
     {
@@ -189,6 +199,46 @@ public class ResourceService
             : new PreEncodedHttpField(cacheControl.getHeader(), cacheControl.getValue());
     }
 
+    /**
+     * @return the min content length of files sent without copying them to user-space buffers, or -1 if disabled
+     */
+    public long getSendFileThreshold()
+    {
+        return _sendFileThreshold;
+    }
+
+    /**
+     * <p>Sets the min content length of files that are sent using
+     * {@link FileChannel#transferTo(long, long, java.nio.channels.WritableByteChannel)},
+     * without copying the file content to user-space buffers.</p>
+     * <p>Zero-copy is only used for whole file responses that are not included,
+     * not wrapped (for example by a gzip interceptor) and sent over a transport
+     * that supports it (for example HTTP/1.1 without TLS).</p>
+     *
+     * @param sendFileThreshold the min content length in bytes, or -1 to disable zero-copy
+     * @see HttpOutput#isSendFileSupported()
+     */
+    public void setSendFileThreshold(long sendFileThreshold)
+    {
+        _sendFileThreshold = sendFileThreshold;
+    }
+
+    /**
+     * @return the number of responses sent without copying the file content to user-space buffers
+     */
+    public long getSendFileResponses()
+    {
+        return _sendFileResponses.sum();
+    }
+
+    /**
+     * @return the number of bytes sent without copying them to user-space buffers
+     */
+    public long getSendFileBytes()
+    {
+        return _sendFileBytes.sum();
+    }
+
     public List<String> getGzipEquivalentFileExtensions()
     {
         return _gzipEquivalentFileExtensions;
@@ -199,6 +249,26 @@ public class ResourceService
         _gzipEquivalentFileExtensions = gzipEquivalentFileExtensions;
     }
 
+    public List<String> getAttachmentFileExtensions()
+    {
+        return _attachmentFileExtensions;
+    }
+
+    /**
+     * <p>Sets the file extensions of the resources that are sent with a
+     * {@code Content-Disposition: attachment} header, so that user agents
+     * save them rather than display them.</p>
+     * <p>The header carries the resource file name, encoded as per RFC 6266.</p>
+     *
+     * @param attachmentFileExtensions the file extensions, for example {@code ".zip"},
+     * or {@code "*"} for all the resources
+     * @see #getContentDisposition(HttpContent)
+     */
+    public void setAttachmentFileExtensions(List<String> attachmentFileExtensions)
+    {
+        _attachmentFileExtensions = attachmentFileExtensions;
+    }
+
     public boolean doGet(HttpServletRequest request, HttpServletResponse response)
         throws ServletException, IOException
     {
@@ -711,13 +781,16 @@ public class ResourceService
                 // write the headers
                 putHeaders(response, content, Response.USE_KNOWN_CONTENT_LENGTH);
 
+                // send the file without copying it to user-space buffers if possible
+                FileChannel file = newSendFileChannel(content, (HttpOutput)out, content_length);
+
                 // write the content asynchronously if supported
                 if (request.isAsyncSupported())
                 {
                     final AsyncContext context = request.startAsync();
                     context.setTimeout(0);
 
-                    ((HttpOutput)out).sendContent(content, new Callback()
+                    Callback callback = new Callback()
                     {
                         @Override
                         public void succeeded()
@@ -749,11 +822,36 @@ public class ResourceService
                         {
                             return String.format("ResourceService@%x$CB", ResourceService.this.hashCode());
                         }
-                    });
+                    };
+
+                    if (file == null)
+                    {
+                        ((HttpOutput)out).sendContent(content, callback);
+                    }
+                    else
+                    {
+                        ((HttpOutput)out).sendContent(file, 0, content_length, new Callback.Nested(callback)
+                        {
+                            @Override
+                            public void succeeded()
+                            {
+                                onSendFile(content_length);
+                                super.succeeded();
+                            }
+                        });
+                    }
                     return false;
                 }
                 // otherwise write content blocking
-                ((HttpOutput)out).sendContent(content);
+                if (file == null)
+                {
+                    ((HttpOutput)out).sendContent(content);
+                }
+                else
+                {
+                    ((HttpOutput)out).sendContent(file, 0, content_length);
+                    onSendFile(content_length);
+                }
             }
         }
         else
@@ -877,6 +975,35 @@ public class ResourceService
         }
     }
 
+    private FileChannel newSendFileChannel(HttpContent content, HttpOutput out, long contentLength)
+    {
+        if (_sendFileThreshold < 0 || contentLength < _sendFileThreshold || !out.isSendFileSupported())
+            return null;
+        try
+        {
+            Resource resource = content.getResource();
+            // The resource file is not the content, for example for compressed variants.
+            if (resource == null || resource.length() != contentLength)
+                return null;
+            File file = resource.getFile();
+            if (file == null)
+                return null;
+            return FileChannel.open(file.toPath(), StandardOpenOption.READ);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Unable to open file channel for {}", content, x);
+            return null;
+        }
+    }
+
+    private void onSendFile(long bytes)
+    {
+        _sendFileResponses.increment();
+        _sendFileBytes.add(bytes);
+    }
+
     protected void putHeaders(HttpServletResponse response, HttpContent content, long contentLength)
     {
         if (response instanceof Response)
@@ -888,6 +1015,12 @@ public class ResourceService
                 fields.add(ACCEPT_RANGES);
             if (_cacheControl != null && !fields.contains(HttpHeader.CACHE_CONTROL))
                 fields.add(_cacheControl);
+            if (!fields.contains(HttpHeader.CONTENT_DISPOSITION))
+            {
+                HttpField contentDisposition = getContentDisposition(content);
+                if (contentDisposition != null)
+                    fields.add(contentDisposition);
+            }
         }
         else
         {
@@ -897,9 +1030,49 @@ public class ResourceService
 
             if (_cacheControl != null && !response.containsHeader(HttpHeader.CACHE_CONTROL.asString()))
                 response.setHeader(_cacheControl.getName(), _cacheControl.getValue());
+
+            if (!response.containsHeader(HttpHeader.CONTENT_DISPOSITION.asString()))
+            {
+                HttpField contentDisposition = getContentDisposition(content);
+                if (contentDisposition != null)
+                    response.setHeader(contentDisposition.getName(), contentDisposition.getValue());
+            }
         }
     }
 
+    /**
+     * <p>Returns the {@code Content-Disposition} field sent with the given content.</p>
+     * <p>The default implementation returns an {@code attachment} disposition
+     * with the resource file name if the file extension is one of the
+     * {@link #setAttachmentFileExtensions(List) attachment file extensions}.</p>
+     *
+     * @param content the content being sent
+     * @return the {@code Content-Disposition} field, or null to send none
+     */
+    protected HttpField getContentDisposition(HttpContent content)
+    {
+        if (_attachmentFileExtensions == null || _attachmentFileExtensions.isEmpty())
+            return null;
+        Resource resource = content.getResource();
+        if (resource == null)
+            return null;
+
+        String name = resource.getName();
+        if (name.endsWith("/"))
+            name = name.substring(0, name.length() - 1);
+        int slash = Math.max(name.lastIndexOf('/'), name.lastIndexOf(File.separatorChar));
+        String fileName = name.substring(slash + 1);
+        if (fileName.isEmpty())
+            return null;
+
+        for (String extension : _attachmentFileExtensions)
+        {
+            if ("*".equals(extension) || StringUtil.endsWithIgnoreCase(fileName, extension))
+                return ContentDisposition.attachment(fileName).asHttpField();
+        }
+        return null;
+    }
+
     public interface WelcomeFactory
     {
 
@@ -16,14 +16,17 @@ package org.eclipse.jetty.server;
 import java.io.IOException;
 import java.io.PrintWriter;
 import java.nio.channels.IllegalSelectorException;
+import java.util.ArrayList;
 import java.util.Collection;
 import java.util.Collections;
 import java.util.EnumSet;
 import java.util.Iterator;
+import java.util.List;
 import java.util.ListIterator;
 import java.util.Locale;
 import java.util.Map;
 import java.util.function.Supplier;
+import java.util.function.UnaryOperator;
 import javax.servlet.ServletOutputStream;
 import javax.servlet.ServletResponse;
 import javax.servlet.ServletResponseWrapper;
@@ -220,39 +223,62 @@ public class Response implements HttpServletResponse
             throw new IllegalArgumentException("Cookie.name cannot be blank/null");
      
         // add the set cookie
-        _fields.add(new SetCookieHttpField(checkSameSite(cookie), getHttpChannel().getHttpConfiguration().getResponseCookieCompliance()));
+        CookieCompliance compliance = getHttpChannel().getHttpConfiguration().getResponseCookieCompliance();
+        _fields.add(new SetCookieHttpField(checkCookie(applyCookieDefaults(cookie), compliance), compliance));
 
         // Expire responses with set-cookie headers so they do not get cached.
         _fields.put(__EXPIRES_01JAN1970);
     }
     
     /**
-     * Check that samesite is set on the cookie. If not, use a 
-     * context default value, if one has been set.
-     * 
+     * Apply the default SameSite, Secure and Partitioned attributes to the cookie.
+     * The defaults configured for the context, if any, take precedence over the
+     * server wide defaults configured in the {@link HttpConfiguration}.
+     *
      * @param cookie the cookie to check
-     * @return either the original cookie, or a new one that has the samesit default set
+     * @return either the original cookie, or a new one that has the defaults applied
      */
-    private HttpCookie checkSameSite(HttpCookie cookie)
+    private HttpCookie applyCookieDefaults(HttpCookie cookie)
     {
-        if (cookie == null || cookie.getSameSite() != null)
-            return cookie;
+        if (cookie == null)
+            return null;
+
+        HttpConfiguration config = getHttpChannel().getHttpConfiguration();
+        Context context = _channel.getRequest().getContext();
+
+        SameSite sameSite = cookie.getSameSite();
+        if (sameSite == null)
+        {
+            sameSite = HttpCookie.getSameSiteDefault(context);
+            if (sameSite == null)
+                sameSite = config.getCookieSameSiteDefault();
+        }
+
+        boolean secure = cookie.isSecure();
+        if (!secure && _channel.getRequest().isSecure())
+        {
+            Boolean secureDefault = HttpCookie.getSecureDefault(context);
+            secure = secureDefault == null ? config.isCookieSecureDefault() : secureDefault;
+        }
+
+        // Only cross site cookies are partitioned, and Partitioned requires Secure.
+        boolean partitioned = cookie.isPartitioned();
+        if (!partitioned && secure && sameSite == SameSite.NONE)
+        {
+            Boolean partitionedDefault = HttpCookie.getPartitionedDefault(context);
+            partitioned = partitionedDefault == null ? config.isCookiePartitionedDefault() : partitionedDefault;
+        }
 
-        //sameSite is not set, use the default configured for the context, if one exists
-        SameSite contextDefault = HttpCookie.getSameSiteDefault(_channel.getRequest().getContext());
-        if (contextDefault == null)
-            return cookie; //no default set
+        if (sameSite == cookie.getSameSite() && secure == cookie.isSecure() && partitioned == cookie.isPartitioned())
+            return cookie;
+        return HttpCookie.build(cookie).sameSite(sameSite).secure(secure).partitioned(partitioned).build();
+    }
 
-        return new HttpCookie(cookie.getName(),
-            cookie.getValue(),
-            cookie.getDomain(),
-            cookie.getPath(),
-            cookie.getMaxAge(),
-            cookie.isHttpOnly(),
-            cookie.isSecure(),
-            cookie.getComment(),
-            cookie.getVersion(),
-            contextDefault);
+    private static HttpCookie checkCookie(HttpCookie cookie, CookieCompliance compliance)
+    {
+        if (cookie != null && !compliance.allows(CookieCompliance.Violation.INVALID_PREFIX))
+            HttpCookie.requireValidPrefix(cookie);
+        return cookie;
     }
 
     @Override
@@ -268,6 +294,7 @@ public class Response implements HttpServletResponse
             // HttpOnly was supported as a comment in cookie flags before the java.net.HttpCookie implementation so need to check that
             boolean httpOnly = cookie.isHttpOnly() || HttpCookie.isHttpOnlyInComment(comment);
             SameSite sameSite = HttpCookie.getSameSiteFromComment(comment);
+            boolean partitioned = HttpCookie.isPartitionedInComment(comment);
             comment = HttpCookie.getCommentWithoutAttributes(comment);
 
             addCookie(new HttpCookie(
@@ -280,7 +307,8 @@ public class Response implements HttpServletResponse
                 cookie.getSecure(),
                 comment,
                 cookie.getVersion(),
-                sameSite));
+                sameSite,
+                partitioned));
         }
     }
 
@@ -325,7 +353,7 @@ public class Response implements HttpServletResponse
                 else if (!cookie.getPath().equals(oldCookie.getPath()))
                     continue;
 
-                i.set(new SetCookieHttpField(checkSameSite(cookie), compliance));
+                i.set(new SetCookieHttpField(checkCookie(applyCookieDefaults(cookie), compliance), compliance));
                 return;
             }
         }
@@ -334,6 +362,73 @@ public class Response implements HttpServletResponse
         addCookie(cookie);
     }
 
+    /**
+     * @return the cookies of the {@code Set-Cookie} headers added so far,
+     * except those added as header values that cannot be parsed
+     */
+    public List<HttpCookie> getHttpCookies()
+    {
+        List<HttpCookie> cookies = new ArrayList<>();
+        for (HttpField field : _fields)
+        {
+            if (field.getHeader() != HttpHeader.SET_COOKIE)
+                continue;
+            HttpCookie cookie = toHttpCookie(field);
+            if (cookie != null)
+                cookies.add(cookie);
+        }
+        return cookies;
+    }
+
+    /**
+     * <p>Rewrites the cookies of the {@code Set-Cookie} headers added so far.</p>
+     * <p>The rewriter is invoked for each cookie and returns either the same cookie
+     * to leave it unchanged, a new cookie to replace it, or null to remove it.
+     * The default attributes are not applied to the rewritten cookies.</p>
+     * <p>{@code Set-Cookie} headers added as header values are parsed, and those
+     * that cannot be parsed as a single cookie are left unchanged; note that
+     * replacing a parsed cookie drops the attributes that are not supported by
+     * {@link HttpCookie}, such as {@code Expires}.</p>
+     * <p>Rewriting the cookies has no effect once the response is committed:
+     * use a {@link CookieCustomizer} to rewrite the cookies of all responses just before the commit.</p>
+     *
+     * @param rewriter the function that rewrites each cookie
+     */
+    public void rewriteCookies(UnaryOperator<HttpCookie> rewriter)
+    {
+        CookieCompliance compliance = getHttpChannel().getHttpConfiguration().getResponseCookieCompliance();
+        for (ListIterator<HttpField> i = _fields.listIterator(); i.hasNext(); )
+        {
+            HttpField field = i.next();
+            if (field.getHeader() != HttpHeader.SET_COOKIE)
+                continue;
+
+            HttpCookie cookie = toHttpCookie(field);
+            if (cookie == null)
+                continue;
+            HttpCookie rewritten = rewriter.apply(cookie);
+            if (rewritten == null)
+                i.remove();
+            else if (rewritten != cookie)
+                i.set(new SetCookieHttpField(checkCookie(rewritten, compliance), compliance));
+        }
+    }
+
+    private static HttpCookie toHttpCookie(HttpField field)
+    {
+        if (field instanceof SetCookieHttpField)
+            return ((SetCookieHttpField)field).getHttpCookie();
+        try
+        {
+            return new HttpCookie(field.getValue());
+        }
+        catch (IllegalArgumentException | IllegalStateException x)
+        {
+            // Not a single valid cookie, leave it unchanged.
+            return null;
+        }
+    }
+
     public boolean containsHeader(String name)
     {
         return _fields.contains(name);
@@ -528,9 +623,35 @@ public class Response implements HttpServletResponse
      */
     public void sendEarlyHint() throws IOException
     {
-        if (!isCommitted())
-            _channel.sendResponse(new MetaData.Response(_channel.getRequest().getHttpVersion(), HttpStatus.EARLY_HINT_103,
-                _channel.getResponse()._fields.asImmutable()), null, true);
+        sendEarlyHints(_fields);
+    }
+
+    /**
+     * <p>Sends a 103 Early Hints interim response as per
+     * <a href="https://datatracker.ietf.org/doc/html/rfc8297">RFC8297</a>,
+     * typically with {@code Link} headers that allow the client to preload
+     * resources while the final response is being prepared.</p>
+     * <p>This method may be called multiple times before the response is committed,
+     * for example to send a first batch of hints as soon as they are known and
+     * further batches later; each invocation sends a distinct 103 response that
+     * contains only the given fields, which are not added to the final response.</p>
+     * <p>Since HTTP/1.0 clients cannot process interim responses, nothing is sent
+     * if the request protocol is older than HTTP/1.1; nothing is sent also if the
+     * response is committed or is being included.</p>
+     *
+     * @param hints the fields of the 103 response
+     * @return whether the 103 response has been sent
+     * @throws IOException if unable to send the 103 response
+     */
+    public boolean sendEarlyHints(HttpFields hints) throws IOException
+    {
+        if (isIncluding() || isCommitted())
+            return false;
+        HttpVersion version = _channel.getRequest().getHttpVersion();
+        if (version == null || version.getVersion() < HttpVersion.HTTP_1_1.getVersion())
+            return false;
+        _channel.sendResponse(new MetaData.Response(version, HttpStatus.EARLY_HINT_103, hints.asImmutable()), null, true);
+        return true;
     }
 
     /**
@@ -0,0 +1,403 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.regex.Pattern;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.component.DumpableCollection;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HeaderPolicy} made of an ordered list of {@link Rule}s.</p>
+ * <p>Each header field is processed by the rules in order: a rule may
+ * normalize the field for the following rules, drop it, or reject the request.
+ * Header names are matched case-insensitively against glob patterns, where
+ * {@code *} matches any sequence of characters, for example {@code X-Internal-*};
+ * header values are optionally matched against regular expressions.</p>
+ * <p>Each rule counts the header fields, or the requests, it applied to.</p>
+ * <p>The {@link HeaderPolicy#isFramingField(HttpField) framing fields} are never
+ * normalized, and rules that drop header fields cannot match them; they can
+ * only be rejected.</p>
+ * <pre>
+ * RuleBasedHeaderPolicy policy = new RuleBasedHeaderPolicy();
+ * policy.normalize();
+ * policy.drop("X-Internal-*");
+ * policy.reject("Content-Length", ".*,.*");
+ * policy.maxHeaders(100);
+ * httpConfiguration.setHeaderPolicy(policy);
+ * </pre>
+ */
+@ManagedObject("Rule based request header policy")
+public class RuleBasedHeaderPolicy implements HeaderPolicy, Dumpable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RuleBasedHeaderPolicy.class);
+    private static final Pattern WHITESPACE = Pattern.compile("[ \t]+");
+    private static final List<HttpHeader> FRAMING_HEADERS = List.of(HttpHeader.CONTENT_LENGTH, HttpHeader.TRANSFER_ENCODING, HttpHeader.HOST);
+
+    private final List<Rule> _rules = new CopyOnWriteArrayList<>();
+
+    /**
+     * <p>Adds a rule that canonicalizes the case of the header names,
+     * for example {@code content-type} to {@code Content-Type}, and that
+     * collapses the sequences of spaces and tabs of the header values to a single space.</p>
+     *
+     * @return the rule
+     */
+    public Rule normalize()
+    {
+        return addRule(new Rule(Mode.NORMALIZE, "*", null, -1));
+    }
+
+    /**
+     * <p>Adds a rule that drops the header fields with the given name.</p>
+     *
+     * @param namePattern the header name glob pattern
+     * @return the rule
+     */
+    public Rule drop(String namePattern)
+    {
+        return drop(namePattern, null);
+    }
+
+    /**
+     * <p>Adds a rule that drops the header fields with the given name and a matching value.</p>
+     *
+     * @param namePattern the header name glob pattern
+     * @param valueRegex the header value regular expression, or null to match any value
+     * @return the rule
+     */
+    public Rule drop(String namePattern, String valueRegex)
+    {
+        return addRule(new Rule(Mode.DROP, namePattern, valueRegex, -1));
+    }
+
+    /**
+     * <p>Adds a rule that rejects with a {@code 400} status code the requests
+     * with a header field with the given name and a matching value.</p>
+     *
+     * @param namePattern the header name glob pattern
+     * @param valueRegex the header value regular expression, or null to match any value
+     * @return the rule
+     */
+    public Rule reject(String namePattern, String valueRegex)
+    {
+        return addRule(new Rule(Mode.REJECT, namePattern, valueRegex, -1));
+    }
+
+    /**
+     * <p>Adds a rule that rejects with a {@code 431} status code the requests
+     * with more than the given number of header fields with the given name.</p>
+     *
+     * @param namePattern the header name glob pattern
+     * @param maxCount the max number of header fields
+     * @return the rule
+     */
+    public Rule maxCount(String namePattern, int maxCount)
+    {
+        if (maxCount < 0)
+            throw new IllegalArgumentException("Invalid max count " + maxCount);
+        return addRule(new Rule(Mode.MAX_COUNT, namePattern, null, maxCount));
+    }
+
+    /**
+     * <p>Adds a rule that rejects with a {@code 431} status code the requests
+     * with more than the given number of header fields.</p>
+     *
+     * @param maxHeaders the max number of header fields
+     * @return the rule
+     */
+    public Rule maxHeaders(int maxHeaders)
+    {
+        return maxCount("*", maxHeaders);
+    }
+
+    private Rule addRule(Rule rule)
+    {
+        _rules.add(rule);
+        return rule;
+    }
+
+    /**
+     * @param rule the rule to remove
+     * @return whether the rule was removed
+     */
+    public boolean removeRule(Rule rule)
+    {
+        return _rules.remove(rule);
+    }
+
+    /**
+     * @return the rules, in order
+     */
+    public List<Rule> getRules()
+    {
+        return new ArrayList<>(_rules);
+    }
+
+    @ManagedAttribute("The rules, with the number of times they applied")
+    public String[] getRuleCounts()
+    {
+        return _rules.stream().map(Rule::toString).toArray(String[]::new);
+    }
+
+    @ManagedOperation(value = "Resets the counts of the rules", impact = "ACTION")
+    public void resetCounts()
+    {
+        _rules.forEach(rule -> rule._count.reset());
+    }
+
+    @Override
+    public HttpField onHeader(HttpField field) throws BadMessageException
+    {
+        for (Rule rule : _rules)
+        {
+            field = rule.onHeader(field);
+            if (field == null)
+                return null;
+        }
+        return field;
+    }
+
+    @Override
+    public void onHeaders(HttpFields fields) throws BadMessageException
+    {
+        for (Rule rule : _rules)
+        {
+            rule.onHeaders(fields);
+        }
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        Dumpable.dumpObjects(out, indent, this, new DumpableCollection("rules", _rules));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[rules=%d]", getClass().getSimpleName(), hashCode(), _rules.size());
+    }
+
+    private static String canonicalName(HttpField field)
+    {
+        HttpHeader header = field.getHeader();
+        if (header != null)
+            return header.asString();
+        String name = field.getName();
+        StringBuilder builder = new StringBuilder(name.length());
+        boolean upper = true;
+        for (int i = 0; i < name.length(); ++i)
+        {
+            char c = name.charAt(i);
+            builder.append(upper ? Character.toUpperCase(c) : Character.toLowerCase(c));
+            upper = c == '-';
+        }
+        return builder.toString();
+    }
+
+    private static Pattern globPattern(String glob)
+    {
+        StringBuilder regex = new StringBuilder();
+        for (String part : glob.split("\\*", -1))
+        {
+            if (regex.length() > 0)
+                regex.append(".*");
+            if (!part.isEmpty())
+                regex.append(Pattern.quote(part));
+        }
+        return Pattern.compile(regex.toString(), Pattern.CASE_INSENSITIVE);
+    }
+
+    public enum Mode
+    {
+        /**
+         * Normalizes the header name case and the header value whitespace.
+         */
+        NORMALIZE,
+        /**
+         * Drops the header field.
+         */
+        DROP,
+        /**
+         * Rejects the request.
+         */
+        REJECT,
+        /**
+         * Rejects the request if it has too many header fields.
+         */
+        MAX_COUNT
+    }
+
+    /**
+     * <p>A rule of a {@link RuleBasedHeaderPolicy}, that counts the times it applied.</p>
+     */
+    public static class Rule
+    {
+        private final LongAdder _count = new LongAdder();
+        private final Mode _mode;
+        private final String _namePattern;
+        private final Pattern _name;
+        private final Pattern _value;
+        private final int _maxCount;
+
+        private Rule(Mode mode, String namePattern, String valueRegex, int maxCount)
+        {
+            if (StringUtil.isBlank(namePattern))
+                throw new IllegalArgumentException("Invalid header name pattern");
+            _mode = Objects.requireNonNull(mode);
+            _namePattern = namePattern;
+            _name = "*".equals(namePattern) ? null : globPattern(namePattern);
+            _value = valueRegex == null ? null : Pattern.compile(valueRegex);
+            _maxCount = maxCount;
+            if (mode == Mode.DROP && _name != null)
+            {
+                for (HttpHeader header : FRAMING_HEADERS)
+                {
+                    if (_name.matcher(header.asString()).matches())
+                        throw new IllegalArgumentException("Cannot drop framing header " + header + " with pattern " + namePattern);
+                }
+            }
+            else if (mode == Mode.DROP)
+            {
+                throw new IllegalArgumentException("Cannot drop framing headers with pattern " + namePattern);
+            }
+        }
+
+        public Mode getMode()
+        {
+            return _mode;
+        }
+
+        public String getNamePattern()
+        {
+            return _namePattern;
+        }
+
+        public String getValueRegex()
+        {
+            return _value == null ? null : _value.pattern();
+        }
+
+        public int getMaxCount()
+        {
+            return _maxCount;
+        }
+
+        /**
+         * @return the number of header fields, or of requests for {@link Mode#MAX_COUNT} rules, this rule applied to
+         */
+        public long getCount()
+        {
+            return _count.sum();
+        }
+
+        private boolean matches(HttpField field)
+        {
+            if (_name != null && !_name.matcher(field.getName()).matches())
+                return false;
+            if (_value == null)
+                return true;
+            String value = field.getValue();
+            return value != null && _value.matcher(value).matches();
+        }
+
+        private HttpField onHeader(HttpField field)
+        {
+            switch (_mode)
+            {
+                case NORMALIZE:
+                {
+                    if (HeaderPolicy.isFramingField(field))
+                        return field;
+                    HttpField normalized = normalize(field);
+                    if (normalized != field)
+                        _count.increment();
+                    return normalized;
+                }
+                case DROP:
+                {
+                    if (!matches(field))
+                        return field;
+                    _count.increment();
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Dropped {} by {}", field, this);
+                    return null;
+                }
+                case REJECT:
+                {
+                    if (!matches(field))
+                        return field;
+                    _count.increment();
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Rejected {} by {}", field, this);
+                    throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Rejected header " + field.getName());
+                }
+                default:
+                    return field;
+            }
+        }
+
+        private void onHeaders(HttpFields fields)
+        {
+            if (_mode != Mode.MAX_COUNT)
+                return;
+            int count = 0;
+            for (HttpField field : fields)
+            {
+                if (matches(field) && ++count > _maxCount)
+                {
+                    _count.increment();
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Too many headers {} by {}", _namePattern, this);
+                    throw new BadMessageException(HttpStatus.REQUEST_HEADER_FIELDS_TOO_LARGE_431, "Too many headers");
+                }
+            }
+        }
+
+        private static HttpField normalize(HttpField field)
+        {
+            String name = canonicalName(field);
+            String value = field.getValue();
+            if (value != null)
+                value = WHITESPACE.matcher(value.trim()).replaceAll(" ");
+            if (name.equals(field.getName()) && Objects.equals(value, field.getValue()))
+                return field;
+            return new HttpField(field.getHeader(), name, value);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s[%s,name=%s,value=%s,max=%d,count=%d]", getClass().getSimpleName(), _mode, _namePattern, getValueRegex(), _maxCount, getCount());
+        }
+    }
+}
@@ -32,10 +32,12 @@ import org.eclipse.jetty.http.PreEncodedHttpField;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.ssl.SslConnection;
 import org.eclipse.jetty.io.ssl.SslConnection.DecryptedEndPoint;
+import org.eclipse.jetty.io.ssl.TlsFingerprint;
 import org.eclipse.jetty.util.Attributes;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.TypeUtil;
 import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.ssl.RevocationChecker;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
 import org.eclipse.jetty.util.ssl.X509;
 import org.slf4j.Logger;
@@ -54,6 +56,26 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
     public static final String JAVAX_SERVLET_REQUEST_KEY_SIZE = "javax.servlet.request.key_size";
     public static final String JAVAX_SERVLET_REQUEST_SSL_SESSION_ID = "javax.servlet.request.ssl_session_id";
     public static final String X509_CERT = "org.eclipse.jetty.server.x509_cert";
+    /**
+     * The name of the request attribute holding the {@link RevocationChecker.Status}
+     * of the client certificates, when a {@link RevocationChecker} is configured.
+     */
+    public static final String REVOCATION_STATUS = "org.eclipse.jetty.server.revocation_status";
+    /**
+     * The name of the request attribute holding the JA3 fingerprint string of the client,
+     * when {@link SslConnectionFactory#setTlsFingerprinting(boolean) TLS fingerprinting} is enabled.
+     */
+    public static final String TLS_JA3 = "org.eclipse.jetty.server.tls_ja3";
+    /**
+     * The name of the request attribute holding the MD5 hash of the JA3 fingerprint of the client,
+     * when {@link SslConnectionFactory#setTlsFingerprinting(boolean) TLS fingerprinting} is enabled.
+     */
+    public static final String TLS_JA3_HASH = "org.eclipse.jetty.server.tls_ja3_hash";
+    /**
+     * The name of the request attribute holding the JA4 fingerprint of the client,
+     * when {@link SslConnectionFactory#setTlsFingerprinting(boolean) TLS fingerprinting} is enabled.
+     */
+    public static final String TLS_JA4 = "org.eclipse.jetty.server.tls_ja4";
 
     private String sslSessionAttribute = "org.eclipse.jetty.servlet.request.ssl_session";
 
@@ -206,6 +228,14 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
             SSLEngine sslEngine = sslConnection.getSSLEngine();
             customize(sslEngine, request);
 
+            TlsFingerprint fingerprint = sslConnection.getTlsFingerprint();
+            if (fingerprint != null)
+            {
+                request.setAttribute(TLS_JA3, fingerprint.getJa3());
+                request.setAttribute(TLS_JA3_HASH, fingerprint.getJa3Hash());
+                request.setAttribute(TLS_JA4, fingerprint.getJa4());
+            }
+
             request.setHttpURI(HttpURI.build(request.getHttpURI()).scheme(HttpScheme.HTTPS));
         }
         else if (endp instanceof ProxyConnectionFactory.ProxyEndPoint)
@@ -326,6 +356,7 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
         private Integer _keySize;
         private String _sessionId;
         private String _sessionAttribute;
+        private RevocationChecker.Status _revocationStatus;
 
         private SslAttributes(Request request, SSLSession sslSession)
         {
@@ -341,6 +372,7 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
                 _keySize = sslSessionData.getKeySize();
                 _sessionId = sslSessionData.getIdStr();
                 _sessionAttribute = getSslSessionAttribute();
+                _revocationStatus = (RevocationChecker.Status)_session.getValue(RevocationChecker.STATUS_ATTRIBUTE);
             }
             catch (Exception e)
             {
@@ -361,6 +393,8 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
                     return _keySize;
                 case JAVAX_SERVLET_REQUEST_SSL_SESSION_ID:
                     return _sessionId;
+                case REVOCATION_STATUS:
+                    return _revocationStatus;
                 default:
                     if (!StringUtil.isEmpty(_sessionAttribute) && _sessionAttribute.equals(name))
                         return _session;
@@ -402,6 +436,7 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
             names.remove(JAVAX_SERVLET_REQUEST_CIPHER_SUITE);
             names.remove(JAVAX_SERVLET_REQUEST_KEY_SIZE);
             names.remove(JAVAX_SERVLET_REQUEST_SSL_SESSION_ID);
+            names.remove(REVOCATION_STATUS);
 
             if (_certs != null)
                 names.add(JAVAX_SERVLET_REQUEST_X_509_CERTIFICATE);
@@ -411,6 +446,8 @@ public class SecureRequestCustomizer implements HttpConfiguration.Customizer
                 names.add(JAVAX_SERVLET_REQUEST_KEY_SIZE);
             if (_sessionId != null)
                 names.add(JAVAX_SERVLET_REQUEST_SSL_SESSION_ID);
+            if (_revocationStatus != null)
+                names.add(REVOCATION_STATUS);
             if (!StringUtil.isEmpty(_sessionAttribute))
                 names.add(_sessionAttribute);
 
@@ -19,7 +19,9 @@ import java.net.InetSocketAddress;
 import java.net.URI;
 import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.Collection;
 import java.util.Enumeration;
+import java.util.LinkedHashSet;
 import java.util.List;
 import java.util.Set;
 import java.util.concurrent.CopyOnWriteArrayList;
@@ -56,6 +58,8 @@ import org.eclipse.jetty.util.annotation.Name;
 import org.eclipse.jetty.util.component.AttributeContainerMap;
 import org.eclipse.jetty.util.component.Graceful;
 import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
 import org.eclipse.jetty.util.thread.AutoLock;
 import org.eclipse.jetty.util.thread.QueuedThreadPool;
 import org.eclipse.jetty.util.thread.ShutdownThread;
@@ -71,7 +75,7 @@ import org.slf4j.LoggerFactory;
  * to run jobs that will eventually call the handle method.
  */
 @ManagedObject(value = "Jetty HTTP Servlet server")
-public class Server extends HandlerWrapper implements Attributes
+public class Server extends HandlerWrapper implements Attributes, Validatable
 {
     private static final Logger LOG = LoggerFactory.getLogger(Server.class);
 
@@ -84,6 +88,7 @@ public class Server extends HandlerWrapper implements Attributes
     private boolean _dumpBeforeStop;
     private ErrorHandler _errorHandler;
     private RequestLog _requestLog;
+    private volatile HandlerInstrumentation _handlerInstrumentation;
     private boolean _dryRun;
     private final AutoLock _dateLock = new AutoLock();
     private volatile DateField _dateField;
@@ -153,6 +158,23 @@ public class Server extends HandlerWrapper implements Attributes
         return _errorHandler;
     }
 
+    /**
+     * @return the instrumentation notified of the handler invocations, or null
+     */
+    public HandlerInstrumentation getHandlerInstrumentation()
+    {
+        return _handlerInstrumentation;
+    }
+
+    /**
+     * @param handlerInstrumentation the instrumentation notified of the handler invocations, or null
+     */
+    public void setHandlerInstrumentation(HandlerInstrumentation handlerInstrumentation)
+    {
+        updateBean(_handlerInstrumentation, handlerInstrumentation);
+        _handlerInstrumentation = handlerInstrumentation;
+    }
+
     public void setRequestLog(RequestLog requestLog)
     {
         updateBean(_requestLog, requestLog);
@@ -352,6 +374,35 @@ public class Server extends HandlerWrapper implements Attributes
         return df._dateField;
     }
 
+    /**
+     * <p>Validates the configuration of this server without starting it.</p>
+     * <p>All the {@link Validatable} components of this server are validated,
+     * for example connectors bind then release their ports and SSL context factories
+     * load their key stores, and all the problems found are reported at once.</p>
+     *
+     * @return the validation report
+     * @see #validate(ValidationReport)
+     */
+    public ValidationReport validate()
+    {
+        ValidationReport report = new ValidationReport();
+        validate(report);
+        return report;
+    }
+
+    @Override
+    public void validate(ValidationReport report)
+    {
+        Collection<Validatable> validatables = new LinkedHashSet<>();
+        getContainedBeans(Validatable.class, validatables);
+        for (Validatable validatable : validatables)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Validating {}", validatable);
+            report.validate(validatable);
+        }
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -16,6 +16,7 @@ package org.eclipse.jetty.server;
 import java.net.InetSocketAddress;
 import java.net.SocketAddress;
 import java.nio.ByteBuffer;
+import java.util.List;
 import javax.net.ssl.SSLEngine;
 import javax.net.ssl.SSLSession;
 
@@ -25,14 +26,21 @@ import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.io.Connection;
 import org.eclipse.jetty.io.EndPoint;
 import org.eclipse.jetty.io.RetainableByteBufferPool;
+import org.eclipse.jetty.io.ssl.ECHProcessor;
 import org.eclipse.jetty.io.ssl.SslConnection;
 import org.eclipse.jetty.io.ssl.SslHandshakeListener;
+import org.eclipse.jetty.io.ssl.SslKeyLogListener;
+import org.eclipse.jetty.io.ssl.TlsFingerprint;
 import org.eclipse.jetty.util.annotation.Name;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.ssl.ECHKey;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
 
 public class SslConnectionFactory extends AbstractConnectionFactory implements ConnectionFactory.Detecting, ConnectionFactory.Configuring
 {
+    private static final Logger LOG = LoggerFactory.getLogger(SslConnectionFactory.class);
     private static final int TLS_ALERT_FRAME_TYPE = 0x15;
     private static final int TLS_HANDSHAKE_FRAME_TYPE = 0x16;
     private static final int TLS_MAJOR_VERSION = 3;
@@ -42,6 +50,9 @@ public class SslConnectionFactory extends AbstractConnectionFactory implements C
     private boolean _directBuffersForEncryption = false;
     private boolean _directBuffersForDecryption = false;
     private boolean _ensureSecureRequestCustomizer = true;
+    private boolean _tlsFingerprinting;
+    private List<ECHKey> _echKeys = List.of();
+    private ECHProcessor.Server _echProcessor;
 
     public SslConnectionFactory()
     {
@@ -106,6 +117,28 @@ public class SslConnectionFactory extends AbstractConnectionFactory implements C
         _ensureSecureRequestCustomizer = ensureSecureRequestCustomizer;
     }
 
+    /**
+     * @return whether the {@link TlsFingerprint} of clients is computed
+     */
+    public boolean isTlsFingerprinting()
+    {
+        return _tlsFingerprinting;
+    }
+
+    /**
+     * <p>Sets whether the {@link TlsFingerprint} of clients is computed from their ClientHello message.</p>
+     * <p>The fingerprint is available from {@link SslConnection#getTlsFingerprint()} and, when a
+     * {@link SecureRequestCustomizer} is configured, from the request attributes
+     * {@link SecureRequestCustomizer#TLS_JA3}, {@link SecureRequestCustomizer#TLS_JA3_HASH}
+     * and {@link SecureRequestCustomizer#TLS_JA4}.</p>
+     *
+     * @param tlsFingerprinting whether the TLS fingerprint of clients is computed
+     */
+    public void setTlsFingerprinting(boolean tlsFingerprinting)
+    {
+        _tlsFingerprinting = tlsFingerprinting;
+    }
+
     @Override
     protected void doStart() throws Exception
     {
@@ -117,6 +150,25 @@ public class SslConnectionFactory extends AbstractConnectionFactory implements C
 
         if (session.getPacketBufferSize() > getInputBufferSize())
             setInputBufferSize(session.getPacketBufferSize());
+
+        _echKeys = _sslContextFactory.getECHKeys();
+        if (!_echKeys.isEmpty())
+        {
+            _echProcessor = ECHProcessor.load(ECHProcessor.Server.class).stream()
+                .filter(processor -> processor.appliesTo(engine))
+                .findFirst()
+                .orElse(null);
+            if (_echProcessor == null)
+                LOG.warn("Encrypted Client Hello not supported by {}, ignoring ECH keys of {}", engine.getClass().getName(), _sslContextFactory);
+        }
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        _echProcessor = null;
+        _echKeys = List.of();
+        super.doStop();
     }
 
     @Override
@@ -151,10 +203,14 @@ public class SslConnectionFactory extends AbstractConnectionFactory implements C
             ? _sslContextFactory.newSSLEngine((InetSocketAddress)remoteSocketAddress)
             : _sslContextFactory.newSSLEngine();
         engine.setUseClientMode(false);
+        ECHProcessor.Server echProcessor = _echProcessor;
+        if (echProcessor != null)
+            echProcessor.configure(engine, _echKeys);
 
         SslConnection sslConnection = newSslConnection(connector, endPoint, engine);
         sslConnection.setRenegotiationAllowed(_sslContextFactory.isRenegotiationAllowed());
         sslConnection.setRenegotiationLimit(_sslContextFactory.getRenegotiationLimit());
+        sslConnection.setTlsFingerprinting(isTlsFingerprinting());
         configure(sslConnection, connector, endPoint);
 
         ConnectionFactory next = connector.getConnectionFactory(_nextProtocol);
@@ -184,6 +240,8 @@ public class SslConnectionFactory extends AbstractConnectionFactory implements C
                 container.getBeans(SslHandshakeListener.class).forEach(sslConnection::addHandshakeListener);
             }
             getBeans(SslHandshakeListener.class).forEach(sslConnection::addHandshakeListener);
+            if (_sslContextFactory.isKeyLogEnabled())
+                sslConnection.addHandshakeListener(new SslKeyLogListener(_sslContextFactory));
         }
         return super.configure(connection, connector, endPoint);
     }
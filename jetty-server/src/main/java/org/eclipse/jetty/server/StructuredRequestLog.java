@@ -0,0 +1,304 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.time.Instant;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+import java.util.function.BiFunction;
+import java.util.function.BiPredicate;
+import javax.net.ssl.SSLEngine;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link RequestLog} that produces, for each request, an ordered map of typed fields
+ * that is then serialized by a pluggable {@link RequestLogFormatter}, for example as JSON
+ * or logfmt, and written to a {@link RequestLog.Writer}.</p>
+ * <p>The standard fields are listed as constants of this class; applications may
+ * add their own fields via {@link #addField(String, BiFunction)}, for example to
+ * log request attributes set by other handlers.
+ * The fields cannot be changed while this request log is started.</p>
+ * <p>The time spent in handlers is logged by wrapping them with a {@link TimingHandler}:
+ * each {@code TimingHandler} adds a field named {@code handler.<name>.us} with the
+ * time in microseconds spent by its wrapped handler, including the handlers it calls.</p>
+ *
+ * @see CustomRequestLog
+ */
+@ManagedObject("Structured Request Log")
+public class StructuredRequestLog extends ContainerLifeCycle implements RequestLog
+{
+    public static final String TIMESTAMP = "timestamp";
+    public static final String CLIENT_ADDRESS = "client.address";
+    public static final String CLIENT_PORT = "client.port";
+    public static final String SERVER_NAME = "server.name";
+    public static final String SERVER_PORT = "server.port";
+    public static final String METHOD = "method";
+    public static final String URI = "uri";
+    public static final String PROTOCOL = "protocol";
+    public static final String STATUS = "status";
+    public static final String USER = "user";
+    public static final String BYTES_RECEIVED = "bytes.received";
+    public static final String BYTES_SENT = "bytes.sent";
+    public static final String LATENCY_MS = "latency.ms";
+    public static final String CONNECTION_PERSISTENT = "connection.persistent";
+    public static final String CONNECTION_REQUESTS = "connection.requests";
+    public static final String STREAM_ID = "stream.id";
+    public static final String TLS_PROTOCOL = "tls.protocol";
+    public static final String TLS_CIPHER_SUITE = "tls.cipher_suite";
+    public static final String REFERER = "referer";
+    public static final String USER_AGENT = "user_agent";
+
+    private static final Logger LOG = LoggerFactory.getLogger(StructuredRequestLog.class);
+    private static final String HANDLER_TIMES_ATTRIBUTE = StructuredRequestLog.class.getName() + ".handlerTimes";
+
+    private final Map<String, BiFunction<Request, Response, Object>> _fields = new LinkedHashMap<>();
+    private final RequestLog.Writer _writer;
+    private final RequestLogFormatter _formatter;
+    private BiPredicate<Request, Response> _filter;
+
+    public StructuredRequestLog()
+    {
+        this(new Slf4jRequestLogWriter(), new RequestLogFormatter.Json());
+    }
+
+    public StructuredRequestLog(RequestLog.Writer writer, RequestLogFormatter formatter)
+    {
+        _writer = Objects.requireNonNull(writer);
+        _formatter = Objects.requireNonNull(formatter);
+        addBean(_writer);
+
+        addField(TIMESTAMP, (request, response) -> Instant.ofEpochMilli(request.getTimeStamp()));
+        addField(CLIENT_ADDRESS, (request, response) -> request.getRemoteAddr());
+        addField(CLIENT_PORT, (request, response) -> request.getRemotePort());
+        addField(SERVER_NAME, (request, response) -> request.getServerName());
+        addField(SERVER_PORT, (request, response) -> request.getServerPort());
+        addField(METHOD, (request, response) -> request.getMethod());
+        addField(URI, (request, response) -> request.getOriginalURI());
+        addField(PROTOCOL, (request, response) -> request.getProtocol());
+        addField(STATUS, (request, response) -> response.getCommittedMetaData().getStatus());
+        addField(USER, (request, response) -> CustomRequestLog.getAuthentication(request, false));
+        addField(BYTES_RECEIVED, (request, response) -> request.getHttpInput().getContentReceived());
+        addField(BYTES_SENT, (request, response) -> response.getHttpChannel().getBytesWritten());
+        addField(LATENCY_MS, (request, response) -> System.currentTimeMillis() - request.getTimeStamp());
+        addField(CONNECTION_PERSISTENT, (request, response) -> request.getHttpChannel().isPersistent());
+        addField(CONNECTION_REQUESTS, (request, response) -> request.getHttpChannel().getConnection().getMessagesIn());
+        addField(STREAM_ID, (request, response) -> getStreamId(request));
+        addField(TLS_PROTOCOL, (request, response) -> getTlsProtocol(request));
+        addField(TLS_CIPHER_SUITE, (request, response) -> request.getAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_CIPHER_SUITE));
+        addField(REFERER, (request, response) -> request.getHeader("Referer"));
+        addField(USER_AGENT, (request, response) -> request.getHeader("User-Agent"));
+    }
+
+    /**
+     * <p>Adds, or replaces, a field to the log entries.</p>
+     * <p>Fields are logged in the order they are added.</p>
+     *
+     * @param name the field name
+     * @param extractor the function that extracts the field value from the request and response
+     * @throws IllegalStateException if this request log is started
+     */
+    public void addField(String name, BiFunction<Request, Response, Object> extractor)
+    {
+        checkNotStarted();
+        _fields.put(Objects.requireNonNull(name), Objects.requireNonNull(extractor));
+    }
+
+    /**
+     * @param name the name of the field to remove from the log entries
+     * @return whether the field was removed
+     * @throws IllegalStateException if this request log is started
+     */
+    public boolean removeField(String name)
+    {
+        checkNotStarted();
+        return _fields.remove(name) != null;
+    }
+
+    private void checkNotStarted()
+    {
+        // The fields are iterated without locking by concurrent calls to log().
+        if (isStarted())
+            throw new IllegalStateException("Started");
+    }
+
+    @ManagedAttribute("The names of the logged fields")
+    public String[] getFieldNames()
+    {
+        return _fields.keySet().toArray(new String[0]);
+    }
+
+    /**
+     * @param filter a predicate that returns true if the request should be logged
+     */
+    public void setFilter(BiPredicate<Request, Response> filter)
+    {
+        _filter = filter;
+    }
+
+    @ManagedAttribute("The RequestLog.Writer")
+    public RequestLog.Writer getWriter()
+    {
+        return _writer;
+    }
+
+    @ManagedAttribute("The RequestLogFormatter")
+    public RequestLogFormatter getFormatter()
+    {
+        return _formatter;
+    }
+
+    @Override
+    public void log(Request request, Response response)
+    {
+        try
+        {
+            if (_filter != null && !_filter.test(request, response))
+                return;
+            _writer.write(_formatter.format(extract(request, response)));
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to log request", x);
+        }
+    }
+
+    /**
+     * <p>Extracts the fields for the given request and response.</p>
+     *
+     * @param request the request to log
+     * @param response the response to log
+     * @return the ordered map of field names to field values
+     */
+    protected Map<String, Object> extract(Request request, Response response)
+    {
+        Map<String, Object> fields = new LinkedHashMap<>(_fields.size() * 2);
+        for (Map.Entry<String, BiFunction<Request, Response, Object>> entry : _fields.entrySet())
+        {
+            Object value;
+            try
+            {
+                value = entry.getValue().apply(request, response);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Unable to extract field {}", entry.getKey(), x);
+                value = null;
+            }
+            fields.put(entry.getKey(), value);
+        }
+        @SuppressWarnings("unchecked")
+        Map<String, Long> handlerTimes = (Map<String, Long>)request.getAttribute(HANDLER_TIMES_ATTRIBUTE);
+        if (handlerTimes != null)
+        {
+            for (Map.Entry<String, Long> entry : handlerTimes.entrySet())
+            {
+                fields.put("handler." + entry.getKey() + ".us", TimeUnit.NANOSECONDS.toMicros(entry.getValue()));
+            }
+        }
+        return fields;
+    }
+
+    private static Long getStreamId(Request request)
+    {
+        long streamId = request.getHttpChannel().getStreamId();
+        return streamId < 0 ? null : streamId;
+    }
+
+    private static String getTlsProtocol(Request request)
+    {
+        EndPoint endPoint = request.getHttpChannel().getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+        {
+            SSLEngine sslEngine = ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getSSLEngine();
+            return sslEngine.getSession().getProtocol();
+        }
+        return null;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), _formatter.getClass().getSimpleName());
+    }
+
+    /**
+     * <p>A {@link HandlerWrapper} that records the time spent in its wrapped handler,
+     * logged by {@link StructuredRequestLog} in the {@code handler.<name>.us} field.</p>
+     * <p>For asynchronous requests, only the time spent in the dispatches is recorded;
+     * the times of multiple dispatches, or of multiple {@code TimingHandler}s with
+     * the same name, are summed.</p>
+     */
+    public static class TimingHandler extends HandlerWrapper
+    {
+        private final String _name;
+
+        public TimingHandler(String name)
+        {
+            this(name, null);
+        }
+
+        public TimingHandler(String name, Handler handler)
+        {
+            _name = Objects.requireNonNull(name);
+            setHandler(handler);
+        }
+
+        /**
+         * @return the name of the timed handler, as logged in the {@code handler.<name>.us} field
+         */
+        public String getName()
+        {
+            return _name;
+        }
+
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+        {
+            long begin = NanoTime.now();
+            try
+            {
+                super.handle(target, baseRequest, request, response);
+            }
+            finally
+            {
+                long elapsed = NanoTime.since(begin);
+                @SuppressWarnings("unchecked")
+                Map<String, Long> handlerTimes = (Map<String, Long>)baseRequest.getAttribute(HANDLER_TIMES_ATTRIBUTE);
+                if (handlerTimes == null)
+                {
+                    // Concurrent dispatches of the same request are not possible.
+                    handlerTimes = new LinkedHashMap<>();
+                    baseRequest.setAttribute(HANDLER_TIMES_ATTRIBUTE, handlerTimes);
+                }
+                handlerTimes.merge(_name, elapsed, Long::sum);
+            }
+        }
+    }
+}
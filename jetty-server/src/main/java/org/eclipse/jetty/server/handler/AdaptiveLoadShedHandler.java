@@ -0,0 +1,529 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.pathmap.PathSpecSet;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.eclipse.jetty.util.thread.ThreadPool;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that protects the server from overload by rejecting a fraction of the requests,
+ * adapting the fraction to the measured server load.</p>
+ * <p>The handler measures the queue latency of the requests, that is the time between the
+ * request arrival and the moment a thread starts to handle it, the service time of the
+ * requests, and the size of the {@link QueuedThreadPool} job queue.
+ * Every {@link #getEvaluationPeriod() evaluation period}, the averages of the period are
+ * compared with the configured thresholds: if any threshold is exceeded, the server is
+ * considered overloaded and the {@link #getShedRatio() shed ratio} is increased by
+ * {@link #getIncreaseStep()}, up to {@link #getMaxShedRatio()}; otherwise the shed ratio is
+ * decreased by {@link #getDecreaseStep()}, so that the server recovers automatically when
+ * the load decreases.</p>
+ * <p>Requests are shed according to their {@link Priority}, see {@link #getPriority(String, Request)}:</p>
+ * <ul>
+ * <li>{@link Priority#CRITICAL} requests, by default those matching the
+ * {@link #getExemptPaths() exempt paths} such as health checks, are never shed;</li>
+ * <li>{@link Priority#NORMAL} requests are shed with a probability twice the shed ratio,
+ * so that they are all shed when the shed ratio reaches {@code 0.5};</li>
+ * <li>{@link Priority#HIGH} requests, by default authenticated requests, are only shed when
+ * the shed ratio exceeds {@code 0.5}, so they are shed last.</li>
+ * </ul>
+ * <p>Shed requests receive a {@link #setRejectStatus(int) reject status} response,
+ * by default {@code 503}, with a {@code Retry-After} header.</p>
+ * <p>This handler is meant to be inserted at the server level, for example with
+ * {@link org.eclipse.jetty.server.Server#insertHandler(HandlerWrapper)}, so that the
+ * queue latency is measured before any other handler.</p>
+ */
+@ManagedObject("Adaptive load shedding handler")
+public class AdaptiveLoadShedHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AdaptiveLoadShedHandler.class);
+
+    private final PathSpecSet _exemptPaths = new PathSpecSet();
+    private final AtomicLong _nextEvaluation = new AtomicLong(NanoTime.now());
+    private final LongAdder _queueLatencySum = new LongAdder();
+    private final LongAdder _queueLatencyCount = new LongAdder();
+    private final LongAdder _serviceTimeSum = new LongAdder();
+    private final LongAdder _serviceTimeCount = new LongAdder();
+    private final LongAdder _accepted = new LongAdder();
+    private final LongAdder _shed = new LongAdder();
+    private final AsyncListener _onCompletion = new AsyncListener()
+    {
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            Request baseRequest = Request.getBaseRequest(event.getAsyncContext().getRequest());
+            if (baseRequest != null)
+                recordServiceTime(System.currentTimeMillis() - baseRequest.getTimeStamp());
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+    };
+    private QueuedThreadPool _threadPool;
+    private volatile double _shedRatio;
+    private volatile long _queueLatency;
+    private volatile long _serviceTime;
+    private long _evaluationPeriod = 1000;
+    private long _maxQueueLatency = 100;
+    private long _maxServiceTime;
+    private int _maxQueueSize;
+    private double _increaseStep = 0.1;
+    private double _decreaseStep = 0.05;
+    private double _maxShedRatio = 0.9;
+    private int _rejectStatus = HttpStatus.SERVICE_UNAVAILABLE_503;
+    private int _retryAfter = 1;
+
+    public AdaptiveLoadShedHandler()
+    {
+        _exemptPaths.add("/livez");
+        _exemptPaths.add("/readyz");
+        _exemptPaths.add("/startupz");
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        ThreadPool threadPool = getServer() == null ? null : getServer().getThreadPool();
+        _threadPool = threadPool instanceof QueuedThreadPool ? (QueuedThreadPool)threadPool : null;
+        _shedRatio = 0;
+        _nextEvaluation.set(NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(getEvaluationPeriod()));
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        _threadPool = null;
+    }
+
+    /**
+     * @return the path specs of the requests that are never shed, by default the health check paths
+     * {@code /livez}, {@code /readyz} and {@code /startupz}
+     */
+    @ManagedAttribute("The path specs of the requests that are never shed")
+    public List<String> getExemptPaths()
+    {
+        return new ArrayList<>(_exemptPaths);
+    }
+
+    /**
+     * @param pathSpecs the path specs of the requests that are never shed, replacing the existing ones
+     */
+    public void setExemptPaths(List<String> pathSpecs)
+    {
+        _exemptPaths.clear();
+        pathSpecs.forEach(this::addExemptPath);
+    }
+
+    /**
+     * @param pathSpec a path spec, such as {@code /health/*}, of requests that are never shed
+     */
+    public void addExemptPath(String pathSpec)
+    {
+        _exemptPaths.add(pathSpec);
+    }
+
+    @ManagedAttribute("The period in milliseconds between load evaluations")
+    public long getEvaluationPeriod()
+    {
+        return _evaluationPeriod;
+    }
+
+    /**
+     * @param evaluationPeriod the period in milliseconds between load evaluations,
+     * or 0 to evaluate the load at every request
+     */
+    public void setEvaluationPeriod(long evaluationPeriod)
+    {
+        if (evaluationPeriod < 0)
+            throw new IllegalArgumentException("Invalid evaluation period " + evaluationPeriod);
+        _evaluationPeriod = evaluationPeriod;
+    }
+
+    @ManagedAttribute("The average queue latency in milliseconds above which the server is overloaded")
+    public long getMaxQueueLatency()
+    {
+        return _maxQueueLatency;
+    }
+
+    /**
+     * @param maxQueueLatency the average queue latency in milliseconds above which the server
+     * is overloaded, or 0 to ignore the queue latency
+     */
+    public void setMaxQueueLatency(long maxQueueLatency)
+    {
+        _maxQueueLatency = maxQueueLatency;
+    }
+
+    @ManagedAttribute("The average service time in milliseconds above which the server is overloaded")
+    public long getMaxServiceTime()
+    {
+        return _maxServiceTime;
+    }
+
+    /**
+     * @param maxServiceTime the average service time in milliseconds above which the server
+     * is overloaded, or 0 (the default) to ignore the service time
+     */
+    public void setMaxServiceTime(long maxServiceTime)
+    {
+        _maxServiceTime = maxServiceTime;
+    }
+
+    @ManagedAttribute("The thread pool queue size above which the server is overloaded")
+    public int getMaxQueueSize()
+    {
+        return _maxQueueSize;
+    }
+
+    /**
+     * @param maxQueueSize the {@link QueuedThreadPool} queue size above which the server
+     * is overloaded, or 0 (the default) to ignore the queue size
+     */
+    public void setMaxQueueSize(int maxQueueSize)
+    {
+        _maxQueueSize = maxQueueSize;
+    }
+
+    @ManagedAttribute("The shed ratio increment when the server is overloaded")
+    public double getIncreaseStep()
+    {
+        return _increaseStep;
+    }
+
+    public void setIncreaseStep(double increaseStep)
+    {
+        if (increaseStep <= 0 || increaseStep > 1)
+            throw new IllegalArgumentException("Invalid increase step " + increaseStep);
+        _increaseStep = increaseStep;
+    }
+
+    @ManagedAttribute("The shed ratio decrement when the server is not overloaded")
+    public double getDecreaseStep()
+    {
+        return _decreaseStep;
+    }
+
+    public void setDecreaseStep(double decreaseStep)
+    {
+        if (decreaseStep <= 0 || decreaseStep > 1)
+            throw new IllegalArgumentException("Invalid decrease step " + decreaseStep);
+        _decreaseStep = decreaseStep;
+    }
+
+    @ManagedAttribute("The max shed ratio")
+    public double getMaxShedRatio()
+    {
+        return _maxShedRatio;
+    }
+
+    /**
+     * @param maxShedRatio the max shed ratio, between 0 and 1; a value of at most {@code 0.5}
+     * never sheds {@link Priority#HIGH} requests
+     */
+    public void setMaxShedRatio(double maxShedRatio)
+    {
+        if (maxShedRatio < 0 || maxShedRatio > 1)
+            throw new IllegalArgumentException("Invalid max shed ratio " + maxShedRatio);
+        _maxShedRatio = maxShedRatio;
+    }
+
+    @ManagedAttribute("The response status of shed requests")
+    public int getRejectStatus()
+    {
+        return _rejectStatus;
+    }
+
+    public void setRejectStatus(int rejectStatus)
+    {
+        if (rejectStatus < HttpStatus.BAD_REQUEST_400 || rejectStatus > 599)
+            throw new IllegalArgumentException("Invalid reject status " + rejectStatus);
+        _rejectStatus = rejectStatus;
+    }
+
+    @ManagedAttribute("The Retry-After seconds of shed requests")
+    public int getRetryAfter()
+    {
+        return _retryAfter;
+    }
+
+    /**
+     * @param retryAfter the {@code Retry-After} seconds of shed requests,
+     * or a negative value to not send the {@code Retry-After} header
+     */
+    public void setRetryAfter(int retryAfter)
+    {
+        _retryAfter = retryAfter;
+    }
+
+    /**
+     * @return the current ratio, between 0 and {@link #getMaxShedRatio()}, of the traffic that is shed
+     */
+    @ManagedAttribute("The current ratio of the traffic that is shed")
+    public double getShedRatio()
+    {
+        return _shedRatio;
+    }
+
+    @ManagedAttribute("The average queue latency in milliseconds of the last evaluation period")
+    public long getQueueLatency()
+    {
+        return _queueLatency;
+    }
+
+    @ManagedAttribute("The average service time in milliseconds of the last evaluation period")
+    public long getServiceTime()
+    {
+        return _serviceTime;
+    }
+
+    @ManagedAttribute("The number of requests accepted")
+    public long getAccepted()
+    {
+        return _accepted.sum();
+    }
+
+    @ManagedAttribute("The number of requests shed")
+    public long getShed()
+    {
+        return _shed.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _accepted.reset();
+        _shed.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        Handler handler = getHandler();
+        if (handler == null || !isStarted())
+            return;
+
+        HttpChannelState state = baseRequest.getHttpChannelState();
+        if (!state.isInitial())
+        {
+            // Async dispatches have already been admitted.
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
+            return;
+        }
+
+        long begin = baseRequest.getTimeStamp();
+        _queueLatencySum.add(Math.max(0, System.currentTimeMillis() - begin));
+        _queueLatencyCount.increment();
+        evaluate();
+
+        if (shouldShed(getPriority(target, baseRequest)))
+        {
+            _shed.increment();
+            reject(baseRequest, response);
+            return;
+        }
+
+        _accepted.increment();
+        try
+        {
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (state.isAsyncStarted())
+                state.addListener(_onCompletion);
+            else
+                recordServiceTime(System.currentTimeMillis() - begin);
+        }
+    }
+
+    /**
+     * <p>Returns the priority of the given request.</p>
+     * <p>Requests that match the {@link #getExemptPaths() exempt paths} are {@link Priority#CRITICAL},
+     * requests that are authenticated or that carry an {@code Authorization} header are
+     * {@link Priority#HIGH}, and the other requests are {@link Priority#NORMAL}.</p>
+     *
+     * @param target the request target
+     * @param baseRequest the request
+     * @return the priority of the request
+     */
+    protected Priority getPriority(String target, Request baseRequest)
+    {
+        if (_exemptPaths.test(target))
+            return Priority.CRITICAL;
+        if (baseRequest.getUserPrincipal() != null || baseRequest.getHeader(HttpHeader.AUTHORIZATION.asString()) != null)
+            return Priority.HIGH;
+        return Priority.NORMAL;
+    }
+
+    private boolean shouldShed(Priority priority)
+    {
+        double ratio = getShedRatio();
+        if (ratio <= 0)
+            return false;
+        double probability;
+        switch (priority)
+        {
+            case CRITICAL:
+                return false;
+            case HIGH:
+                probability = 2 * ratio - 1;
+                break;
+            default:
+                probability = 2 * ratio;
+                break;
+        }
+        return probability > 0 && (probability >= 1 || ThreadLocalRandom.current().nextDouble() < probability);
+    }
+
+    private void recordServiceTime(long serviceTime)
+    {
+        _serviceTimeSum.add(Math.max(0, serviceTime));
+        _serviceTimeCount.increment();
+    }
+
+    private void evaluate()
+    {
+        long now = NanoTime.now();
+        long next = _nextEvaluation.get();
+        if (NanoTime.isBefore(now, next))
+            return;
+        // Only one thread evaluates the period.
+        if (!_nextEvaluation.compareAndSet(next, now + TimeUnit.MILLISECONDS.toNanos(getEvaluationPeriod())))
+            return;
+
+        long queueLatencySum = _queueLatencySum.sumThenReset();
+        long queueLatencyCount = _queueLatencyCount.sumThenReset();
+        long queueLatency = queueLatencyCount == 0 ? 0 : queueLatencySum / queueLatencyCount;
+        long serviceTimeSum = _serviceTimeSum.sumThenReset();
+        long serviceTimeCount = _serviceTimeCount.sumThenReset();
+        long serviceTime = serviceTimeCount == 0 ? 0 : serviceTimeSum / serviceTimeCount;
+        QueuedThreadPool threadPool = _threadPool;
+        int queueSize = threadPool == null ? 0 : threadPool.getQueueSize();
+        _queueLatency = queueLatency;
+        _serviceTime = serviceTime;
+
+        boolean overloaded = isOverloaded(queueLatency, serviceTime, queueSize);
+        double ratio = _shedRatio;
+        double newRatio = overloaded
+            ? Math.min(getMaxShedRatio(), ratio + getIncreaseStep())
+            : Math.max(0, ratio - getDecreaseStep());
+        _shedRatio = newRatio;
+
+        if (ratio != newRatio)
+        {
+            if (ratio == 0)
+                LOG.warn("Server overloaded, shedding requests: queueLatency={}ms serviceTime={}ms queueSize={}", queueLatency, serviceTime, queueSize);
+            else if (newRatio == 0)
+                LOG.info("Server recovered, stopped shedding requests");
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("Evaluated overloaded={} queueLatency={}ms serviceTime={}ms queueSize={} shedRatio={}", overloaded, queueLatency, serviceTime, queueSize, newRatio);
+    }
+
+    /**
+     * <p>Returns whether the server is overloaded, given the measurements of the last evaluation period.</p>
+     *
+     * @param queueLatency the average queue latency in milliseconds
+     * @param serviceTime the average service time in milliseconds
+     * @param queueSize the thread pool queue size
+     * @return whether the server is overloaded
+     */
+    protected boolean isOverloaded(long queueLatency, long serviceTime, int queueSize)
+    {
+        long maxQueueLatency = getMaxQueueLatency();
+        if (maxQueueLatency > 0 && queueLatency > maxQueueLatency)
+            return true;
+        long maxServiceTime = getMaxServiceTime();
+        if (maxServiceTime > 0 && serviceTime > maxServiceTime)
+            return true;
+        int maxQueueSize = getMaxQueueSize();
+        return maxQueueSize > 0 && queueSize > maxQueueSize;
+    }
+
+    private void reject(Request baseRequest, HttpServletResponse response) throws IOException
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Shedding {}, shedRatio={}", baseRequest, getShedRatio());
+        baseRequest.setHandled(true);
+        int retryAfter = getRetryAfter();
+        if (retryAfter >= 0)
+            response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(retryAfter));
+        response.sendError(getRejectStatus(), "Server overloaded");
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,shedRatio=%.2f}", getClass().getSimpleName(), hashCode(), getState(), getShedRatio());
+    }
+
+    /**
+     * <p>The priority of a request, that determines whether it can be shed.</p>
+     */
+    public enum Priority
+    {
+        /**
+         * <p>Requests that are never shed.</p>
+         */
+        CRITICAL,
+        /**
+         * <p>Requests that are shed last.</p>
+         */
+        HIGH,
+        /**
+         * <p>Requests that are shed first.</p>
+         */
+        NORMAL
+    }
+}
@@ -20,6 +20,7 @@ import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 
 /**
@@ -86,7 +87,7 @@ public class AsyncDelayHandler extends HandlerWrapper
         // Handle the request
         try
         {
-            _handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
         }
         finally
         {
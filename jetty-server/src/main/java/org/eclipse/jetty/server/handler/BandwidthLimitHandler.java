@@ -0,0 +1,288 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.TimeUnit;
+import java.util.function.Function;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.io.BandwidthLimiter;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that limits the throughput, in bytes per second, of response content.</p>
+ * <p>By default each request is limited independently; a
+ * {@link #setKeyExtractor(Function) key extractor} may be configured so that
+ * all the requests with the same key share the same {@link BandwidthLimiter},
+ * for example to enforce fairness among tenants.</p>
+ * <p>Response content is written in chunks of at most the bytes available in
+ * the limiter, and writes are asynchronously delayed when the limiter is
+ * exhausted, so that this handler works for any HTTP protocol version.</p>
+ * <p>This handler does not limit the request content; to limit the read throughput
+ * use a {@link org.eclipse.jetty.server.BandwidthLimitConnectionFactory}, that
+ * limits whole connections at the EndPoint level.</p>
+ */
+@ManagedObject("Bandwidth limiting handler")
+public class BandwidthLimitHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(BandwidthLimitHandler.class);
+
+    private final ConcurrentMap<String, BandwidthLimiter> _limiters = new ConcurrentHashMap<>();
+    private Function<Request, String> _keyExtractor;
+    private long _writeRate = -1;
+    private long _burst = 16 * 1024;
+    private int _maxKeys = 10_000;
+
+    @ManagedAttribute("The max response content bytes per second, or -1 for unlimited")
+    public long getWriteRate()
+    {
+        return _writeRate;
+    }
+
+    public void setWriteRate(long writeRate)
+    {
+        _writeRate = writeRate;
+        _limiters.clear();
+    }
+
+    @ManagedAttribute("The max number of bytes written in a burst")
+    public long getBurst()
+    {
+        return _burst;
+    }
+
+    public void setBurst(long burst)
+    {
+        if (burst <= 0)
+            throw new IllegalArgumentException("Invalid burst " + burst);
+        _burst = burst;
+        _limiters.clear();
+    }
+
+    @ManagedAttribute("The max number of keys tracked before idle keys are evicted")
+    public int getMaxKeys()
+    {
+        return _maxKeys;
+    }
+
+    public void setMaxKeys(int maxKeys)
+    {
+        _maxKeys = maxKeys;
+    }
+
+    public Function<Request, String> getKeyExtractor()
+    {
+        return _keyExtractor;
+    }
+
+    /**
+     * @param keyExtractor the function that extracts the key of the shared limiter from
+     * the request, returning {@code null} if the request must be limited independently;
+     * a {@code null} function limits every request independently
+     * @see RateLimitHandler#remoteAddress()
+     * @see RateLimitHandler#header(String)
+     */
+    public void setKeyExtractor(Function<Request, String> keyExtractor)
+    {
+        _keyExtractor = keyExtractor;
+        _limiters.clear();
+    }
+
+    @ManagedAttribute("The number of keys currently tracked")
+    public int getKeys()
+    {
+        return _limiters.size();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        long rate = getWriteRate();
+        HttpOutput output = baseRequest.getResponse().getHttpOutput();
+        // Asynchronous dispatches of the same request are already limited.
+        if (rate > 0 && !isLimited(output))
+        {
+            BandwidthLimiter limiter = getLimiter(baseRequest, rate);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Limiting {} with {}", baseRequest, limiter);
+            Scheduler scheduler = baseRequest.getHttpChannel().getScheduler();
+            output.setInterceptor(new BandwidthLimitInterceptor(this, output.getInterceptor(), limiter, scheduler, null));
+        }
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private boolean isLimited(HttpOutput output)
+    {
+        for (HttpOutput.Interceptor interceptor = output.getInterceptor(); interceptor != null; interceptor = interceptor.getNextInterceptor())
+        {
+            if (interceptor instanceof BandwidthLimitInterceptor && ((BandwidthLimitInterceptor)interceptor)._owner == this)
+                return true;
+        }
+        return false;
+    }
+
+    private BandwidthLimiter getLimiter(Request request, long rate)
+    {
+        String key = _keyExtractor == null ? null : _keyExtractor.apply(request);
+        if (key == null)
+            return new BandwidthLimiter(rate, getBurst());
+        BandwidthLimiter limiter = _limiters.get(key);
+        if (limiter != null)
+            return limiter;
+        if (_limiters.size() >= _maxKeys)
+            _limiters.values().removeIf(l -> l.getAvailable() >= l.getBurst());
+        return _limiters.computeIfAbsent(key, k -> new BandwidthLimiter(rate, getBurst()));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[writeRate=%d/s,burst=%d]", getClass().getSimpleName(), hashCode(), _writeRate, _burst);
+    }
+
+    /**
+     * <p>Writes the response content in chunks of at most the bytes available in the
+     * limiter, asynchronously delaying the writes when the limiter is exhausted.</p>
+     * <p>Also used by {@link ResourceQuotaHandler} to limit the output rate of a context.</p>
+     */
+    static class BandwidthLimitInterceptor implements HttpOutput.Interceptor
+    {
+        private final Handler _owner;
+        private final HttpOutput.Interceptor _next;
+        private final BandwidthLimiter _limiter;
+        private final Scheduler _scheduler;
+        private final Runnable _onDelay;
+
+        /**
+         * @param owner the handler that installed this interceptor
+         * @param next the next interceptor
+         * @param limiter the limiter, possibly shared with other requests
+         * @param scheduler the scheduler of the delayed writes
+         * @param onDelay invoked every time a write is delayed, or null
+         */
+        BandwidthLimitInterceptor(Handler owner, HttpOutput.Interceptor next, BandwidthLimiter limiter, Scheduler scheduler, Runnable onDelay)
+        {
+            _owner = owner;
+            _next = Objects.requireNonNull(next);
+            _limiter = limiter;
+            _scheduler = scheduler;
+            _onDelay = onDelay;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            new Writer(content, last, callback).iterate();
+        }
+
+        private class Writer extends IteratingCallback
+        {
+            private final ByteBuffer _content;
+            private final boolean _last;
+            private final Callback _callback;
+            private int _chunk;
+            private boolean _done;
+
+            private Writer(ByteBuffer content, boolean last, Callback callback)
+            {
+                _content = content;
+                _last = last;
+                _callback = callback;
+            }
+
+            @Override
+            protected Action process()
+            {
+                if (_chunk > 0)
+                {
+                    // The previous chunk has been written.
+                    _content.position(_content.position() + _chunk);
+                    _chunk = 0;
+                }
+
+                if (_done)
+                    return Action.SUCCEEDED;
+
+                int remaining = _content == null ? 0 : _content.remaining();
+                if (remaining == 0)
+                {
+                    // Nothing to limit, but the write may flush or complete the response.
+                    _done = true;
+                    _next.write(_content, _last, this);
+                    return Action.SCHEDULED;
+                }
+
+                long delay = _limiter.getDelay();
+                if (delay > 0)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Delaying write by {} ns with {}", delay, _limiter);
+                    if (_onDelay != null)
+                        _onDelay.run();
+                    _scheduler.schedule(this::succeeded, delay, TimeUnit.NANOSECONDS);
+                    return Action.SCHEDULED;
+                }
+
+                int length = (int)Math.min(remaining, Math.max(1, _limiter.getAvailable()));
+                _limiter.reserve(length);
+                _chunk = length;
+                _done = length == remaining;
+                ByteBuffer slice = _content.slice();
+                slice.limit(length);
+                _next.write(slice, _last && _done, this);
+                return Action.SCHEDULED;
+            }
+
+            @Override
+            protected void onCompleteSuccess()
+            {
+                _callback.succeeded();
+            }
+
+            @Override
+            protected void onCompleteFailure(Throwable cause)
+            {
+                _callback.failed(cause);
+            }
+
+            @Override
+            public InvocationType getInvocationType()
+            {
+                return _callback.getInvocationType();
+            }
+        }
+    }
+}
@@ -0,0 +1,650 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.BufferedReader;
+import java.io.ByteArrayInputStream;
+import java.io.Closeable;
+import java.io.File;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.InputStreamReader;
+import java.io.OutputStream;
+import java.nio.charset.Charset;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.util.Objects;
+import javax.servlet.AsyncContext;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ReadListener;
+import javax.servlet.ServletException;
+import javax.servlet.ServletInputStream;
+import javax.servlet.ServletRequest;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletRequestWrapper;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpHeaderValue;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.ByteArrayOutputStream2;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.MultiMap;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A Handler that reads the whole request content before handling the request,
+ * so that the content can be read multiple times by the wrapped handlers, for
+ * example to verify a signature before processing the content, or to replay
+ * the content after an authentication challenge or a failed attempt.</p>
+ * <p>The content is read asynchronously, without blocking a thread while waiting
+ * for the content to arrive; content up to {@link #setMaxMemorySize(int)} bytes
+ * is buffered in memory, while larger content is spooled to a temporary file in
+ * {@link #setTempDir(Path)}. The temporary file is deleted when the request
+ * completes.</p>
+ * <p>Requests with content larger than {@link #setMaxContentSize(long)} bytes
+ * are rejected with a {@code 413} response.</p>
+ * <p>The wrapped handlers receive a request whose {@link ServletRequest#getInputStream()}
+ * and {@link ServletRequest#getReader()} methods return, at every invocation, a new
+ * stream that reads the content from the beginning.
+ * The buffered content is also available via {@link #getBufferedContent(ServletRequest)}.</p>
+ * <p>Like {@link AsyncDelayHandler}, the dispatch type of the request is adjusted
+ * so that the request does not appear asynchronous to the wrapped handlers.</p>
+ */
+@ManagedObject("Buffers the request content so that it can be read multiple times")
+public class BufferedRequestContentHandler extends AsyncDelayHandler
+{
+    /**
+     * The name of the request attribute that holds the {@link BufferedContent}.
+     */
+    public static final String BUFFERED_CONTENT_ATTRIBUTE = BufferedRequestContentHandler.class.getName() + ".content";
+
+    private static final Logger LOG = LoggerFactory.getLogger(BufferedRequestContentHandler.class);
+
+    private int _maxMemorySize = 64 * 1024;
+    private long _maxContentSize = -1;
+    private Path _tempDir = new File(System.getProperty("java.io.tmpdir")).toPath();
+
+    /**
+     * @return the max content size in bytes that is buffered in memory
+     */
+    @ManagedAttribute("The max content size in bytes buffered in memory")
+    public int getMaxMemorySize()
+    {
+        return _maxMemorySize;
+    }
+
+    /**
+     * @param maxMemorySize the max content size in bytes that is buffered in memory,
+     * larger content is spooled to a temporary file
+     */
+    public void setMaxMemorySize(int maxMemorySize)
+    {
+        if (maxMemorySize < 0)
+            throw new IllegalArgumentException("Invalid max memory size " + maxMemorySize);
+        _maxMemorySize = maxMemorySize;
+    }
+
+    /**
+     * @return the max content size in bytes, or -1 for unlimited content size
+     */
+    @ManagedAttribute("The max content size in bytes, or -1 for unlimited")
+    public long getMaxContentSize()
+    {
+        return _maxContentSize;
+    }
+
+    /**
+     * @param maxContentSize the max content size in bytes, or -1 for unlimited content size
+     */
+    public void setMaxContentSize(long maxContentSize)
+    {
+        _maxContentSize = maxContentSize;
+    }
+
+    @ManagedAttribute("The directory of the temporary files")
+    public Path getTempDir()
+    {
+        return _tempDir;
+    }
+
+    public void setTempDir(Path tempDir)
+    {
+        _tempDir = Objects.requireNonNull(tempDir);
+    }
+
+    /**
+     * @param request the request
+     * @return the buffered content of the request, or null if the request content was not buffered
+     */
+    public static BufferedContent getBufferedContent(ServletRequest request)
+    {
+        return (BufferedContent)request.getAttribute(BUFFERED_CONTENT_ATTRIBUTE);
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        BufferedContent content = getBufferedContent(baseRequest);
+        if (content != null)
+        {
+            super.handle(target, baseRequest, new BufferedContentRequest(request, content), response);
+            return;
+        }
+
+        if (!shouldBuffer(baseRequest))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        long contentLength = baseRequest.getContentLengthLong();
+        if (_maxContentSize >= 0 && contentLength > _maxContentSize)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejecting content length {} > {} for {}", contentLength, _maxContentSize, baseRequest);
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.PAYLOAD_TOO_LARGE_413);
+            return;
+        }
+
+        super.handle(target, baseRequest, request, response);
+    }
+
+    /**
+     * <p>Returns whether the content of the given request should be buffered.</p>
+     * <p>By default, requests that declare a content, either via the
+     * {@code Content-Length} header, via chunked transfer encoding, or
+     * implicitly as in HTTP/2 and HTTP/3, are buffered.</p>
+     *
+     * @param request the request
+     * @return whether the request content should be buffered
+     */
+    protected boolean shouldBuffer(Request request)
+    {
+        long contentLength = request.getContentLengthLong();
+        if (contentLength > 0)
+            return true;
+        if (contentLength == 0)
+            return false;
+        if (request.getHttpInput().isFinished())
+            return false;
+        if (request.getHttpFields().contains(HttpHeader.TRANSFER_ENCODING))
+            return true;
+        HttpVersion version = request.getHttpVersion();
+        return version != null && version.getVersion() >= HttpVersion.HTTP_2.getVersion();
+    }
+
+    @Override
+    protected boolean startHandling(Request request, boolean restart)
+    {
+        if (restart)
+            prepareFormParameters(request);
+        return restart || getBufferedContent(request) != null || !shouldBuffer(request);
+    }
+
+    @Override
+    protected void delayHandling(Request request, AsyncContext context)
+    {
+        BufferedContent content = new BufferedContent(_maxMemorySize, _tempDir);
+        context.setTimeout(0);
+        context.addListener(new AsyncListener()
+        {
+            @Override
+            public void onComplete(AsyncEvent event)
+            {
+                content.close();
+            }
+
+            @Override
+            public void onTimeout(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onError(AsyncEvent event)
+            {
+            }
+
+            @Override
+            public void onStartAsync(AsyncEvent event)
+            {
+                event.getAsyncContext().addListener(this);
+            }
+        });
+
+        try
+        {
+            ServletInputStream input = request.getInputStream();
+            input.setReadListener(new Spooler(request, context, input, content));
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not read content of {}", request, x);
+            content.close();
+            context.complete();
+        }
+    }
+
+    /**
+     * <p>Invoked when a chunk of content has been read, before it is buffered.</p>
+     * <p>Subclasses may inspect the content as it arrives, for example to validate
+     * it without waiting for the whole content, and reject the request by throwing
+     * an exception, which is then passed to {@link #onContentFailure(Request, Throwable)}.</p>
+     *
+     * @param request the request
+     * @param bytes the content bytes
+     * @param offset the offset of the content in the bytes array
+     * @param length the length of the content
+     * @throws Exception to reject the request
+     */
+    protected void onContent(Request request, byte[] bytes, int offset, int length) throws Exception
+    {
+    }
+
+    /**
+     * <p>Invoked when the whole content has been read, before handling the request.</p>
+     *
+     * @param request the request
+     * @throws Exception to reject the request
+     * @see #onContent(Request, byte[], int, int)
+     */
+    protected void onContentComplete(Request request) throws Exception
+    {
+    }
+
+    /**
+     * <p>Invoked when the content cannot be buffered, either because reading
+     * it failed or because it was rejected, to send the error response.</p>
+     *
+     * @param request the request
+     * @param failure the failure
+     * @throws IOException if the error response cannot be sent
+     */
+    protected void onContentFailure(Request request, Throwable failure) throws IOException
+    {
+        HttpServletResponse response = request.getResponse();
+        if (!response.isCommitted())
+            response.sendError(failure instanceof BadMessageException
+                ? ((BadMessageException)failure).getCode()
+                : HttpStatus.BAD_REQUEST_400);
+    }
+
+    private void prepareFormParameters(Request request)
+    {
+        // The content of form requests has been consumed by this handler, so the
+        // form parameters must be extracted from the buffered content.
+        BufferedContent content = getBufferedContent(request);
+        String contentType = request.getContentType();
+        if (content == null || contentType == null)
+            return;
+        if (!MimeTypes.Type.FORM_ENCODED.is(HttpField.valueParameters(contentType, null)))
+            return;
+        if (!request.getHttpChannel().getHttpConfiguration().isFormEncodedMethod(request.getMethod()))
+            return;
+
+        try (InputStream input = content.newInputStream())
+        {
+            MultiMap<String> parameters = new MultiMap<>();
+            String encoding = request.getCharacterEncoding();
+            request.newParameterParser().parse(input, encoding == null ? null : Charset.forName(encoding), parameters);
+            request.setContentParameters(parameters);
+        }
+        catch (IOException | IllegalArgumentException | IllegalStateException x)
+        {
+            throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Unable to parse form content", x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[memory=%d,max=%d]", getClass().getSimpleName(), hashCode(), _maxMemorySize, _maxContentSize);
+    }
+
+    private class Spooler implements ReadListener
+    {
+        private final byte[] _buffer = new byte[4096];
+        private final Request _request;
+        private final AsyncContext _context;
+        private final ServletInputStream _input;
+        private final BufferedContent _content;
+        private boolean _done;
+
+        private Spooler(Request request, AsyncContext context, ServletInputStream input, BufferedContent content)
+        {
+            _request = request;
+            _context = context;
+            _input = input;
+            _content = content;
+        }
+
+        @Override
+        public void onDataAvailable() throws IOException
+        {
+            while (!_done && _input.isReady())
+            {
+                int read = _input.read(_buffer);
+                if (read < 0)
+                    return;
+                if (_maxContentSize >= 0 && _content.getLength() + read > _maxContentSize)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Rejecting content length > {} for {}", _maxContentSize, _request);
+                    _done = true;
+                    _content.close();
+                    HttpServletResponse response = _request.getResponse();
+                    response.setHeader(HttpHeader.CONNECTION.asString(), HttpHeaderValue.CLOSE.asString());
+                    response.sendError(HttpStatus.PAYLOAD_TOO_LARGE_413);
+                    _context.complete();
+                    return;
+                }
+                try
+                {
+                    onContent(_request, _buffer, 0, read);
+                }
+                catch (Throwable x)
+                {
+                    // The rest of the content is not read.
+                    reject(x, true);
+                    return;
+                }
+                _content.write(_buffer, 0, read);
+            }
+        }
+
+        private void reject(Throwable failure, boolean close)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejecting content of {}", _request, failure);
+            _done = true;
+            _content.close();
+            try
+            {
+                if (close)
+                    _request.getResponse().setHeader(HttpHeader.CONNECTION.asString(), HttpHeaderValue.CLOSE.asString());
+                onContentFailure(_request, failure);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not send error for {}", _request, x);
+            }
+            _context.complete();
+        }
+
+        @Override
+        public void onAllDataRead() throws IOException
+        {
+            if (_done)
+                return;
+            try
+            {
+                onContentComplete(_request);
+            }
+            catch (Throwable x)
+            {
+                reject(x, false);
+                return;
+            }
+            _done = true;
+            _content.complete();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Buffered {} for {}", _content, _request);
+            _request.setAttribute(BUFFERED_CONTENT_ATTRIBUTE, _content);
+            _context.dispatch();
+        }
+
+        @Override
+        public void onError(Throwable failure)
+        {
+            if (_done)
+                return;
+            _done = true;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not buffer content of {}", _request, failure);
+            _content.close();
+            try
+            {
+                onContentFailure(_request, failure);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not send error for {}", _request, x);
+            }
+            _context.complete();
+        }
+    }
+
+    /**
+     * <p>The buffered request content, either in memory or in a temporary file.</p>
+     */
+    public static class BufferedContent implements Closeable
+    {
+        private final int _maxMemorySize;
+        private final Path _tempDir;
+        private ByteArrayOutputStream2 _memory = new ByteArrayOutputStream2();
+        private Path _path;
+        private OutputStream _output;
+        private long _length;
+        private boolean _closed;
+
+        private BufferedContent(int maxMemorySize, Path tempDir)
+        {
+            _maxMemorySize = maxMemorySize;
+            _tempDir = tempDir;
+        }
+
+        private void write(byte[] bytes, int offset, int length) throws IOException
+        {
+            if (_closed)
+                throw new IOException("Closed");
+            if (_output == null && _length + length > _maxMemorySize)
+            {
+                _path = Files.createTempFile(_tempDir, "BufferedRequest", "");
+                _output = Files.newOutputStream(_path, StandardOpenOption.WRITE);
+                _output.write(_memory.getBuf(), 0, _memory.getCount());
+                _memory = null;
+            }
+            if (_output == null)
+                _memory.write(bytes, offset, length);
+            else
+                _output.write(bytes, offset, length);
+            _length += length;
+        }
+
+        private void complete() throws IOException
+        {
+            if (_output != null)
+                _output.close();
+        }
+
+        /**
+         * @return the length in bytes of the content
+         */
+        public long getLength()
+        {
+            return _length;
+        }
+
+        /**
+         * @return whether the content is buffered in memory
+         */
+        public boolean isInMemory()
+        {
+            return _path == null;
+        }
+
+        /**
+         * @return the path of the temporary file that holds the content, or null if the content is buffered in memory
+         */
+        public Path getPath()
+        {
+            return _path;
+        }
+
+        /**
+         * @return a new stream that reads the content from the beginning
+         * @throws IOException if the stream cannot be created
+         */
+        public InputStream newInputStream() throws IOException
+        {
+            if (_closed)
+                throw new IOException("Closed");
+            if (_path != null)
+                return Files.newInputStream(_path, StandardOpenOption.READ);
+            return new ByteArrayInputStream(_memory.getBuf(), 0, _memory.getCount());
+        }
+
+        /**
+         * <p>Releases the content, deleting the temporary file, if any.</p>
+         * <p>This method is called when the request completes.</p>
+         */
+        @Override
+        public void close()
+        {
+            if (_closed)
+                return;
+            _closed = true;
+            IO.close(_output);
+            _output = null;
+            _memory = null;
+            if (_path != null)
+            {
+                try
+                {
+                    Files.deleteIfExists(_path);
+                }
+                catch (Throwable t)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not immediately delete file (delaying to jvm exit) {}", _path, t);
+                    _path.toFile().deleteOnExit();
+                }
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[length=%d,path=%s]", getClass().getSimpleName(), hashCode(), _length, _path);
+        }
+    }
+
+    private static class BufferedContentRequest extends HttpServletRequestWrapper
+    {
+        private final BufferedContent _content;
+
+        private BufferedContentRequest(HttpServletRequest request, BufferedContent content)
+        {
+            super(request);
+            _content = content;
+        }
+
+        @Override
+        public ServletInputStream getInputStream() throws IOException
+        {
+            return new BufferedContentInputStream(_content.newInputStream());
+        }
+
+        @Override
+        public BufferedReader getReader() throws IOException
+        {
+            String encoding = getCharacterEncoding();
+            Charset charset = encoding == null ? StandardCharsets.ISO_8859_1 : Charset.forName(encoding);
+            return new BufferedReader(new InputStreamReader(getInputStream(), charset));
+        }
+    }
+
+    private static class BufferedContentInputStream extends ServletInputStream
+    {
+        private final InputStream _input;
+        private boolean _finished;
+
+        private BufferedContentInputStream(InputStream input)
+        {
+            _input = input;
+        }
+
+        @Override
+        public boolean isFinished()
+        {
+            return _finished;
+        }
+
+        @Override
+        public boolean isReady()
+        {
+            // The content is already available.
+            return true;
+        }
+
+        @Override
+        public void setReadListener(ReadListener readListener)
+        {
+            try
+            {
+                readListener.onDataAvailable();
+                readListener.onAllDataRead();
+            }
+            catch (Throwable x)
+            {
+                readListener.onError(x);
+            }
+        }
+
+        @Override
+        public int read() throws IOException
+        {
+            int read = _input.read();
+            if (read < 0)
+                _finished = true;
+            return read;
+        }
+
+        @Override
+        public int read(byte[] b, int off, int len) throws IOException
+        {
+            int read = _input.read(b, off, len);
+            if (read < 0)
+                _finished = true;
+            return read;
+        }
+
+        @Override
+        public int available() throws IOException
+        {
+            return _input.available();
+        }
+
+        @Override
+        public void close() throws IOException
+        {
+            _input.close();
+        }
+    }
+}
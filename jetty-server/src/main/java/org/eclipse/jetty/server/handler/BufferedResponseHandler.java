@@ -26,6 +26,7 @@ import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.http.MimeTypes;
 import org.eclipse.jetty.http.pathmap.PathSpecSet;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.HttpChannel;
 import org.eclipse.jetty.server.HttpOutput;
 import org.eclipse.jetty.server.HttpOutput.Interceptor;
@@ -144,7 +145,7 @@ public class BufferedResponseHandler extends HandlerWrapper
             {
                 if (LOG.isDebugEnabled())
                     LOG.debug("{} already intercepting {}", this, request);
-                _handler.handle(target, baseRequest, request, response);
+                HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
                 return;
             }
             interceptor = interceptor.getNextInterceptor();
@@ -155,7 +156,7 @@ public class BufferedResponseHandler extends HandlerWrapper
         {
             if (LOG.isDebugEnabled())
                 LOG.debug("{} excluded by method {}", this, request);
-            _handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
             return;
         }
 
@@ -164,7 +165,7 @@ public class BufferedResponseHandler extends HandlerWrapper
         {
             if (LOG.isDebugEnabled())
                 LOG.debug("{} excluded by path {}", this, request);
-            _handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
             return;
         }
 
@@ -179,7 +180,7 @@ public class BufferedResponseHandler extends HandlerWrapper
                     LOG.debug("{} excluded by path suffix mime type {}", this, request);
 
                 // handle normally without setting vary header
-                _handler.handle(target, baseRequest, request, response);
+                HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
                 return;
             }
         }
@@ -187,7 +188,7 @@ public class BufferedResponseHandler extends HandlerWrapper
         // Install buffered interceptor and handle.
         out.setInterceptor(newBufferedInterceptor(baseRequest.getHttpChannel(), out.getInterceptor()));
         if (_handler != null)
-            _handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
     }
 
     protected BufferedInterceptor newBufferedInterceptor(HttpChannel httpChannel, Interceptor interceptor)
@@ -0,0 +1,569 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.Deque;
+import java.util.List;
+import java.util.Locale;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncContext;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that partitions the server thread capacity between virtual hosts or contexts,
+ * so that a slow or misbehaving application cannot starve the others on a shared server.</p>
+ * <p>Each {@link Partition} is a bulkhead that limits the number of threads that concurrently
+ * handle its requests; requests are assigned to the partition that matches their virtual host
+ * and the longest context path, or to the {@link #getDefaultPartition() default partition}
+ * if no partition matches.</p>
+ * <p>Requests in excess of the partition limit are asynchronously suspended, up to the
+ * partition max queue size, until a thread of the partition is available or the queue timeout
+ * expires; requests that cannot be queued or whose queue timeout expires receive a
+ * {@link #setRejectStatus(int) reject status} response, by default {@code 503},
+ * with a {@code Retry-After} header.</p>
+ * <p>Like {@link ThreadLimitHandler}, a permit is only held while a thread handles the request,
+ * so asynchronously suspended requests do not consume the partition capacity.</p>
+ * <p>This handler is meant to be inserted at the server level, for example with
+ * {@link org.eclipse.jetty.server.Server#insertHandler(HandlerWrapper)}.</p>
+ */
+@ManagedObject("Bulkhead handler")
+public class BulkheadHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(BulkheadHandler.class);
+    private static final String PERMIT = "o.e.j.s.h.BH.PERMIT";
+
+    private final List<Partition> _partitions = new CopyOnWriteArrayList<>();
+    private final Partition _defaultPartition = new Partition("default", 0, 0);
+    private int _rejectStatus = HttpStatus.SERVICE_UNAVAILABLE_503;
+    private int _retryAfter = 1;
+
+    public BulkheadHandler()
+    {
+        addBean(_defaultPartition);
+    }
+
+    /**
+     * <p>Adds a partition, that must then be associated with virtual hosts
+     * via {@link Partition#addVirtualHost(String)} or with context paths via
+     * {@link Partition#addContextPath(String)}.</p>
+     *
+     * @param name the partition name
+     * @param maxConcurrency the max number of threads handling the partition requests
+     * @param maxQueueSize the max number of requests waiting for a thread of the partition
+     * @return the partition
+     */
+    public Partition addPartition(@Name("name") String name, @Name("maxConcurrency") int maxConcurrency, @Name("maxQueueSize") int maxQueueSize)
+    {
+        if (getPartition(name) != null)
+            throw new IllegalArgumentException("Duplicate partition " + name);
+        Partition partition = new Partition(name, maxConcurrency, maxQueueSize);
+        _partitions.add(partition);
+        addBean(partition);
+        return partition;
+    }
+
+    public boolean removePartition(Partition partition)
+    {
+        removeBean(partition);
+        return _partitions.remove(partition);
+    }
+
+    public Partition getPartition(String name)
+    {
+        if (_defaultPartition.getName().equals(name))
+            return _defaultPartition;
+        return _partitions.stream().filter(p -> p.getName().equals(name)).findFirst().orElse(null);
+    }
+
+    public List<Partition> getPartitions()
+    {
+        return new ArrayList<>(_partitions);
+    }
+
+    /**
+     * @return the partition of the requests that match no other partition,
+     * by default with no concurrency limit
+     */
+    public Partition getDefaultPartition()
+    {
+        return _defaultPartition;
+    }
+
+    @ManagedAttribute("The response status of rejected requests")
+    public int getRejectStatus()
+    {
+        return _rejectStatus;
+    }
+
+    public void setRejectStatus(int rejectStatus)
+    {
+        if (rejectStatus < HttpStatus.BAD_REQUEST_400 || rejectStatus > 599)
+            throw new IllegalArgumentException("Invalid reject status " + rejectStatus);
+        _rejectStatus = rejectStatus;
+    }
+
+    @ManagedAttribute("The Retry-After seconds of rejected requests")
+    public int getRetryAfter()
+    {
+        return _retryAfter;
+    }
+
+    /**
+     * @param retryAfter the {@code Retry-After} seconds of rejected requests,
+     * or a negative value to not send the {@code Retry-After} header
+     */
+    public void setRetryAfter(int retryAfter)
+    {
+        _retryAfter = retryAfter;
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _defaultPartition.resetStatistics();
+        _partitions.forEach(Partition::resetStatistics);
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        Handler handler = getHandler();
+        if (handler == null || !isStarted())
+            return;
+
+        // Do we already have a permit granted while the request was queued?
+        Partition partition = (Partition)baseRequest.getAttribute(PERMIT);
+        if (partition != null)
+        {
+            baseRequest.removeAttribute(PERMIT);
+        }
+        else
+        {
+            partition = findPartition(target, baseRequest);
+            switch (partition.acquire(baseRequest))
+            {
+                case QUEUED:
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Queued {} in {}", baseRequest, partition);
+                    return;
+                case REJECTED:
+                    reject(baseRequest, response, partition);
+                    return;
+                default:
+                    break;
+            }
+        }
+
+        try
+        {
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
+        }
+        finally
+        {
+            partition.release();
+        }
+    }
+
+    /**
+     * <p>Returns the partition of the given request.</p>
+     * <p>The partition is the one whose virtual hosts match the request
+     * server name, if any, and whose context paths match the request target
+     * with the longest context path, if any.</p>
+     *
+     * @param target the request target
+     * @param baseRequest the request
+     * @return the partition of the request, or the default partition
+     */
+    protected Partition findPartition(String target, Request baseRequest)
+    {
+        String host = baseRequest.getServerName();
+        Partition result = _defaultPartition;
+        int best = -1;
+        for (Partition partition : _partitions)
+        {
+            int match = partition.match(host, target);
+            if (match > best)
+            {
+                best = match;
+                result = partition;
+            }
+        }
+        return result;
+    }
+
+    private void reject(Request baseRequest, HttpServletResponse response, Partition partition) throws IOException
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Rejecting {}, {} saturated", baseRequest, partition);
+        baseRequest.setHandled(true);
+        int retryAfter = getRetryAfter();
+        if (retryAfter >= 0)
+            response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(retryAfter));
+        response.sendError(getRejectStatus(), "Bulkhead saturated: " + partition.getName());
+    }
+
+    private enum Acquire
+    {
+        ACQUIRED, QUEUED, REJECTED
+    }
+
+    /**
+     * <p>A bulkhead that limits the concurrent threads and the queued requests
+     * of a set of virtual hosts or context paths.</p>
+     */
+    @ManagedObject("Bulkhead partition")
+    public class Partition implements Dumpable
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final Deque<Waiter> _queue = new ArrayDeque<>();
+        private final List<String> _virtualHosts = new CopyOnWriteArrayList<>();
+        private final List<String> _contextPaths = new CopyOnWriteArrayList<>();
+        private final LongAdder _accepted = new LongAdder();
+        private final LongAdder _rejected = new LongAdder();
+        private final LongAdder _timedOut = new LongAdder();
+        private final String _name;
+        private volatile int _maxConcurrency;
+        private volatile int _maxQueueSize;
+        private volatile long _queueTimeout = 30000;
+        private int _active;
+        private int _maxActive;
+
+        private Partition(String name, int maxConcurrency, int maxQueueSize)
+        {
+            _name = name;
+            _maxConcurrency = maxConcurrency;
+            _maxQueueSize = maxQueueSize;
+        }
+
+        @ManagedAttribute("The partition name")
+        public String getName()
+        {
+            return _name;
+        }
+
+        @ManagedAttribute("The max number of threads handling requests, or 0 for no limit")
+        public int getMaxConcurrency()
+        {
+            return _maxConcurrency;
+        }
+
+        public void setMaxConcurrency(int maxConcurrency)
+        {
+            _maxConcurrency = maxConcurrency;
+        }
+
+        @ManagedAttribute("The max number of requests waiting for a thread")
+        public int getMaxQueueSize()
+        {
+            return _maxQueueSize;
+        }
+
+        public void setMaxQueueSize(int maxQueueSize)
+        {
+            _maxQueueSize = maxQueueSize;
+        }
+
+        @ManagedAttribute("The max time in milliseconds a request waits for a thread, or 0 to wait forever")
+        public long getQueueTimeout()
+        {
+            return _queueTimeout;
+        }
+
+        public void setQueueTimeout(long queueTimeout)
+        {
+            _queueTimeout = queueTimeout;
+        }
+
+        /**
+         * @param virtualHost a virtual host name, or a wildcard such as {@code *.example.com}
+         */
+        public void addVirtualHost(String virtualHost)
+        {
+            _virtualHosts.add(virtualHost.toLowerCase(Locale.ENGLISH));
+        }
+
+        public List<String> getVirtualHosts()
+        {
+            return new ArrayList<>(_virtualHosts);
+        }
+
+        /**
+         * @param contextPath a context path such as {@code /app}
+         */
+        public void addContextPath(String contextPath)
+        {
+            if (contextPath.length() > 1 && contextPath.endsWith("/"))
+                contextPath = contextPath.substring(0, contextPath.length() - 1);
+            _contextPaths.add(contextPath);
+        }
+
+        public List<String> getContextPaths()
+        {
+            return new ArrayList<>(_contextPaths);
+        }
+
+        @ManagedAttribute("The number of threads handling requests")
+        public int getActive()
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                return _active;
+            }
+        }
+
+        @ManagedAttribute("The max number of threads that concurrently handled requests")
+        public int getMaxActive()
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                return _maxActive;
+            }
+        }
+
+        @ManagedAttribute("The number of requests waiting for a thread")
+        public int getQueueSize()
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                return _queue.size();
+            }
+        }
+
+        @ManagedAttribute("The ratio between the active threads and the max concurrency")
+        public double getSaturation()
+        {
+            int max = getMaxConcurrency();
+            return max <= 0 ? 0 : (double)getActive() / max;
+        }
+
+        @ManagedAttribute("The number of requests handled")
+        public long getAccepted()
+        {
+            return _accepted.sum();
+        }
+
+        @ManagedAttribute("The number of requests rejected because the queue was full")
+        public long getRejected()
+        {
+            return _rejected.sum();
+        }
+
+        @ManagedAttribute("The number of requests rejected because the queue timeout expired")
+        public long getTimedOut()
+        {
+            return _timedOut.sum();
+        }
+
+        private void resetStatistics()
+        {
+            _accepted.reset();
+            _rejected.reset();
+            _timedOut.reset();
+            try (AutoLock ignored = _lock.lock())
+            {
+                _maxActive = _active;
+            }
+        }
+
+        /**
+         * @return -1 if the partition does not match, otherwise the length of the matching context path
+         */
+        private int match(String host, String target)
+        {
+            if (_virtualHosts.isEmpty() && _contextPaths.isEmpty())
+                return -1;
+            if (!_virtualHosts.isEmpty() && !matchesVirtualHost(host))
+                return -1;
+            if (_contextPaths.isEmpty())
+                return 0;
+            int best = -1;
+            for (String contextPath : _contextPaths)
+            {
+                boolean matches = "/".equals(contextPath) || target.equals(contextPath) ||
+                    target.startsWith(contextPath) && target.charAt(contextPath.length()) == '/';
+                if (matches && contextPath.length() > best)
+                    best = contextPath.length();
+            }
+            return best;
+        }
+
+        private boolean matchesVirtualHost(String host)
+        {
+            if (host == null)
+                return false;
+            host = host.toLowerCase(Locale.ENGLISH);
+            for (String virtualHost : _virtualHosts)
+            {
+                if (virtualHost.startsWith("*."))
+                {
+                    if (host.endsWith(virtualHost.substring(1)))
+                        return true;
+                }
+                else if (virtualHost.equals(host))
+                {
+                    return true;
+                }
+            }
+            return false;
+        }
+
+        private Acquire acquire(Request baseRequest)
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                int max = getMaxConcurrency();
+                if (max <= 0 || _active < max)
+                {
+                    _accepted.increment();
+                    if (++_active > _maxActive)
+                        _maxActive = _active;
+                    return Acquire.ACQUIRED;
+                }
+
+                if (_queue.size() < getMaxQueueSize())
+                {
+                    AsyncContext asyncContext = baseRequest.startAsync();
+                    asyncContext.setTimeout(getQueueTimeout());
+                    Waiter waiter = new Waiter(asyncContext);
+                    asyncContext.addListener(waiter);
+                    _queue.offerLast(waiter);
+                    return Acquire.QUEUED;
+                }
+
+                _rejected.increment();
+                return Acquire.REJECTED;
+            }
+        }
+
+        private void release()
+        {
+            Waiter waiter;
+            try (AutoLock ignored = _lock.lock())
+            {
+                waiter = _queue.pollFirst();
+                if (waiter == null)
+                    --_active;
+                else
+                    _accepted.increment();
+            }
+            // Transfer the permit to the waiting request.
+            if (waiter != null)
+            {
+                try
+                {
+                    waiter._asyncContext.getRequest().setAttribute(PERMIT, this);
+                    waiter._asyncContext.dispatch();
+                }
+                catch (Throwable x)
+                {
+                    // The waiting request has concurrently expired or failed.
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Could not dispatch {}", waiter._asyncContext, x);
+                    release();
+                }
+            }
+        }
+
+        private boolean dequeue(Waiter waiter)
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                return _queue.remove(waiter);
+            }
+        }
+
+        @Override
+        public void dump(Appendable out, String indent) throws IOException
+        {
+            Dumpable.dumpObjects(out, indent, this,
+                Dumpable.named("virtualHosts", _virtualHosts),
+                Dumpable.named("contextPaths", _contextPaths));
+        }
+
+        @Override
+        public String toString()
+        {
+            try (AutoLock ignored = _lock.lock())
+            {
+                return String.format("%s@%x[%s,active=%d/%d,queued=%d/%d]", getClass().getSimpleName(), hashCode(),
+                    _name, _active, _maxConcurrency, _queue.size(), _maxQueueSize);
+            }
+        }
+
+        private class Waiter implements AsyncListener
+        {
+            private final AsyncContext _asyncContext;
+
+            private Waiter(AsyncContext asyncContext)
+            {
+                _asyncContext = asyncContext;
+            }
+
+            @Override
+            public void onTimeout(AsyncEvent event) throws IOException
+            {
+                if (!dequeue(this))
+                    return;
+                _timedOut.increment();
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Queue timeout {} in {}", _asyncContext.getRequest(), Partition.this);
+                HttpServletResponse response = (HttpServletResponse)event.getSuppliedResponse();
+                int retryAfter = getRetryAfter();
+                if (retryAfter >= 0)
+                    response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(retryAfter));
+                response.sendError(getRejectStatus(), "Bulkhead queue timeout: " + _name);
+                _asyncContext.complete();
+            }
+
+            @Override
+            public void onComplete(AsyncEvent event)
+            {
+                dequeue(this);
+            }
+
+            @Override
+            public void onError(AsyncEvent event)
+            {
+                dequeue(this);
+            }
+
+            @Override
+            public void onStartAsync(AsyncEvent event)
+            {
+            }
+        }
+    }
+}
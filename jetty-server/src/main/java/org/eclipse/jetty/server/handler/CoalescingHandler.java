@@ -0,0 +1,445 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.EnumSet;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.Function;
+import javax.servlet.AsyncContext;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.server.HttpChannel;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that coalesces concurrent identical requests, so that expensive
+ * responses are produced only once.</p>
+ * <p>The first request for a given {@link #setKeyFunction(Function) key} proceeds
+ * to the wrapped handler, while the concurrent requests with the same key are
+ * asynchronously suspended until the first response completes; they then receive
+ * a copy of that response.
+ * By default the key is made of the method, the URI and the values of the
+ * {@link #setVaryHeaders(String...) vary headers}, and only {@code GET} requests
+ * are coalesced.
+ * Requests carrying credentials in the {@code Authorization} or {@code Cookie}
+ * headers are never coalesced by the default key, since their responses may
+ * be specific to the user.</p>
+ * <p>A response is shared only if it has status {@code 200}, does not set cookies,
+ * is not marked {@code private} or {@code no-store}, and its content is at most
+ * {@link #setMaxContentLength(int) maxContentLength} bytes; otherwise the
+ * suspended requests proceed to the wrapped handler independently.
+ * Suspended requests also proceed independently if the first response does not
+ * complete within {@link #setMaxWaitMs(long) maxWaitMs}.</p>
+ * <p>Responses are copied as sent on the network, so this handler should
+ * be placed before handlers that modify the response content such as
+ * {@link org.eclipse.jetty.server.handler.gzip.GzipHandler}, or the vary
+ * headers should include {@code Accept-Encoding}, as they do by default.</p>
+ */
+@ManagedObject("Request coalescing handler")
+public class CoalescingHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CoalescingHandler.class);
+    private static final String RESPONSE = CoalescingHandler.class.getName() + ".response";
+    private static final String BYPASS = CoalescingHandler.class.getName() + ".bypass";
+    private static final EnumSet<HttpHeader> SKIPPED_HEADERS = EnumSet.of(
+        HttpHeader.CONNECTION,
+        HttpHeader.KEEP_ALIVE,
+        HttpHeader.TRANSFER_ENCODING,
+        HttpHeader.CONTENT_LENGTH,
+        HttpHeader.DATE,
+        HttpHeader.SERVER);
+
+    private final ConcurrentMap<String, Flight> _flights = new ConcurrentHashMap<>();
+    private final LongAdder _produced = new LongAdder();
+    private final LongAdder _coalesced = new LongAdder();
+    private final Set<String> _methods = new HashSet<>(Set.of(HttpMethod.GET.asString()));
+    private List<String> _varyHeaders = List.of(HttpHeader.ACCEPT.asString(), HttpHeader.ACCEPT_ENCODING.asString());
+    private Function<Request, String> _keyFunction = this::key;
+    private int _maxContentLength = 1024 * 1024;
+    private long _maxWaitMs = 30000;
+
+    @ManagedAttribute("The HTTP methods of the requests that are coalesced")
+    public Set<String> getMethods()
+    {
+        return Set.copyOf(_methods);
+    }
+
+    public void setMethods(String... methods)
+    {
+        _methods.clear();
+        _methods.addAll(Arrays.asList(methods));
+    }
+
+    @ManagedAttribute("The request headers that are part of the default key")
+    public List<String> getVaryHeaders()
+    {
+        return _varyHeaders;
+    }
+
+    /**
+     * @param headers the names of the request headers whose values are part of the default key
+     */
+    public void setVaryHeaders(String... headers)
+    {
+        _varyHeaders = List.of(headers);
+    }
+
+    public Function<Request, String> getKeyFunction()
+    {
+        return _keyFunction;
+    }
+
+    /**
+     * @param keyFunction the function that returns the key that identifies identical requests,
+     * or {@code null} if the request must not be coalesced
+     */
+    public void setKeyFunction(Function<Request, String> keyFunction)
+    {
+        _keyFunction = Objects.requireNonNull(keyFunction);
+    }
+
+    @ManagedAttribute("The max length of the response content that is shared")
+    public int getMaxContentLength()
+    {
+        return _maxContentLength;
+    }
+
+    public void setMaxContentLength(int maxContentLength)
+    {
+        _maxContentLength = maxContentLength;
+    }
+
+    @ManagedAttribute("The max time in ms a request waits for an identical request to complete")
+    public long getMaxWaitMs()
+    {
+        return _maxWaitMs;
+    }
+
+    public void setMaxWaitMs(long maxWaitMs)
+    {
+        _maxWaitMs = maxWaitMs;
+    }
+
+    @ManagedAttribute("The number of requests currently being coalesced")
+    public int getFlights()
+    {
+        return _flights.size();
+    }
+
+    @ManagedAttribute("The number of requests that produced a response for coalesced requests")
+    public long getRequestsProduced()
+    {
+        return _produced.sum();
+    }
+
+    @ManagedAttribute("The number of requests that waited for an identical request")
+    public long getRequestsCoalesced()
+    {
+        return _coalesced.sum();
+    }
+
+    @ManagedOperation("Resets the statistics")
+    public void resetStatistics()
+    {
+        _produced.reset();
+        _coalesced.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        HttpChannel.TransientListeners.addTo(getServer());
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // A suspended request that has been resumed.
+        Shared shared = (Shared)baseRequest.getAttribute(RESPONSE);
+        if (shared != null)
+        {
+            baseRequest.removeAttribute(RESPONSE);
+            baseRequest.setHandled(true);
+            shared.writeTo(response);
+            return;
+        }
+        if (baseRequest.getAttribute(BYPASS) != null)
+        {
+            baseRequest.removeAttribute(BYPASS);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        String key = _methods.contains(baseRequest.getMethod()) ? _keyFunction.apply(baseRequest) : null;
+        if (key == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        Flight flight = new Flight(key);
+        Flight existing = _flights.putIfAbsent(key, flight);
+        if (existing == null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Producing {} for {}", key, baseRequest);
+            _produced.increment();
+            baseRequest.getHttpChannel().addListener(flight);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        AsyncContext asyncContext = baseRequest.startAsync();
+        asyncContext.setTimeout(_maxWaitMs);
+        asyncContext.addListener(new WaitListener(existing));
+        if (existing.park(asyncContext))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Coalescing {} for {}", baseRequest, key);
+            _coalesced.increment();
+        }
+        else
+        {
+            // The flight completed concurrently, proceed independently.
+            baseRequest.setAttribute(BYPASS, Boolean.TRUE);
+            asyncContext.dispatch();
+        }
+    }
+
+    private String key(Request request)
+    {
+        HttpFields headers = request.getHttpFields();
+        if (headers.contains(HttpHeader.AUTHORIZATION) || headers.contains(HttpHeader.COOKIE))
+            return null;
+        StringBuilder builder = new StringBuilder(128);
+        builder.append(request.getMethod()).append(' ').append(request.getRequestURI());
+        String query = request.getQueryString();
+        if (query != null)
+            builder.append('?').append(query);
+        for (String header : _varyHeaders)
+        {
+            builder.append('\n').append(header).append(':');
+            String value = request.getHeader(header);
+            if (value != null)
+                builder.append(value);
+        }
+        return builder.toString();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[methods=%s,vary=%s]", getClass().getSimpleName(), hashCode(), _methods, _varyHeaders);
+    }
+
+    private static class Shared
+    {
+        private final int _status;
+        private final HttpFields _fields;
+        private final byte[] _content;
+
+        private Shared(int status, HttpFields fields, byte[] content)
+        {
+            _status = status;
+            _fields = fields;
+            _content = content;
+        }
+
+        private void writeTo(HttpServletResponse response) throws IOException
+        {
+            response.setStatus(_status);
+            for (HttpField field : _fields)
+            {
+                response.addHeader(field.getName(), field.getValue());
+            }
+            response.setContentLength(_content.length);
+            response.getOutputStream().write(_content);
+        }
+    }
+
+    private class Flight implements HttpChannel.Listener
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final List<AsyncContext> _waiters = new ArrayList<>();
+        private final String _key;
+        private ByteArrayOutputStream _content = new ByteArrayOutputStream();
+        private int _status;
+        private HttpFields _fields;
+        private boolean _complete;
+
+        private Flight(String key)
+        {
+            _key = key;
+        }
+
+        private boolean park(AsyncContext asyncContext)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_complete)
+                    return false;
+                _waiters.add(asyncContext);
+                return true;
+            }
+        }
+
+        private boolean unpark(AsyncContext asyncContext)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _waiters.remove(asyncContext);
+            }
+        }
+
+        @Override
+        public void onResponseCommit(Request request)
+        {
+            MetaData.Response metaData = request.getResponse().getCommittedMetaData();
+            int status = metaData.getStatus();
+            HttpFields fields = metaData.getFields();
+            if (status != HttpStatus.OK_200 || !isShareable(fields))
+            {
+                _content = null;
+                return;
+            }
+            _status = status;
+            _fields = HttpFields.build(fields, SKIPPED_HEADERS).asImmutable();
+        }
+
+        private boolean isShareable(HttpFields fields)
+        {
+            if (fields.contains(HttpHeader.SET_COOKIE))
+                return false;
+            return !fields.contains(HttpHeader.CACHE_CONTROL, "private") &&
+                !fields.contains(HttpHeader.CACHE_CONTROL, "no-store");
+        }
+
+        @Override
+        public void onResponseContent(Request request, ByteBuffer content)
+        {
+            if (_content == null)
+                return;
+            if (_content.size() + content.remaining() > _maxContentLength)
+            {
+                _content = null;
+                return;
+            }
+            try
+            {
+                BufferUtil.writeTo(content, _content);
+            }
+            catch (IOException x)
+            {
+                _content = null;
+            }
+        }
+
+        @Override
+        public void onResponseFailure(Request request, Throwable failure)
+        {
+            _content = null;
+        }
+
+        @Override
+        public void onComplete(Request request)
+        {
+            _flights.remove(_key, this);
+
+            List<AsyncContext> waiters;
+            try (AutoLock l = _lock.lock())
+            {
+                _complete = true;
+                waiters = new ArrayList<>(_waiters);
+                _waiters.clear();
+            }
+
+            Shared shared = _content == null || _fields == null ? null : new Shared(_status, _fields, _content.toByteArray());
+            if (LOG.isDebugEnabled())
+                LOG.debug("Completed {} for {} waiters, shared={}", _key, waiters.size(), shared != null);
+            for (AsyncContext asyncContext : waiters)
+            {
+                asyncContext.getRequest().setAttribute(shared == null ? BYPASS : RESPONSE, shared == null ? Boolean.TRUE : shared);
+                asyncContext.dispatch();
+            }
+        }
+    }
+
+    private class WaitListener implements AsyncListener
+    {
+        private final Flight _flight;
+
+        private WaitListener(Flight flight)
+        {
+            _flight = flight;
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+            AsyncContext asyncContext = event.getAsyncContext();
+            if (_flight.unpark(asyncContext))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Timed out waiting for {}", _flight._key);
+                asyncContext.getRequest().setAttribute(BYPASS, Boolean.TRUE);
+                asyncContext.dispatch();
+            }
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+            _flight.unpark(event.getAsyncContext());
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+        }
+    }
+}
@@ -0,0 +1,471 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.Function;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.QuotedQualityCSV;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.QuotedStringTokenizer;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that performs proactive content negotiation among a declared set of {@link Representation}s.</p>
+ * <p>Each representation has a media type and optionally a charset, a language and a content coding,
+ * and a {@link Producer} that generates its content. For each request, the quality of each representation
+ * is computed from the {@code Accept}, {@code Accept-Charset}, {@code Accept-Language} and
+ * {@code Accept-Encoding} request headers, as specified by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc7231#section-5.3">RFC 7231, section 5.3</a>:
+ * the quality for each header is the one of the most specific matching range, and the overall quality
+ * is the product of these qualities and of the {@link Representation#getQuality() source quality}.
+ * The representation with the highest quality is produced, with its {@code Content-Type},
+ * {@code Content-Language} and {@code Content-Encoding} headers already set; ties are resolved
+ * in favor of the representation added first.</p>
+ * <p>If no representation is acceptable, a {@code 406} response is sent, unless
+ * {@link #isFallbackToDefault()} is true, in which case the first representation is produced.
+ * In all cases the response has a {@code Vary} header that lists the request headers for
+ * which the available representations differ.</p>
+ * <p>Applications that negotiate in their own code may use {@link #negotiate(HttpFields, List)}
+ * and {@link #getVary(List)} directly.</p>
+ */
+@ManagedObject("Content negotiation handler")
+public class ContentNegotiationHandler extends AbstractHandler
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ContentNegotiationHandler.class);
+    /**
+     * The request attribute name under which the negotiated {@link Representation} is stored.
+     */
+    public static final String REPRESENTATION_ATTRIBUTE = ContentNegotiationHandler.class.getName() + ".representation";
+
+    private final List<Representation> _representations = new CopyOnWriteArrayList<>();
+    private final LongAdder _notAcceptable = new LongAdder();
+    private boolean _fallbackToDefault;
+
+    /**
+     * @param representation the representation to add, with lower priority than those already added
+     */
+    public void addRepresentation(Representation representation)
+    {
+        _representations.add(Objects.requireNonNull(representation));
+    }
+
+    public boolean removeRepresentation(Representation representation)
+    {
+        return _representations.remove(representation);
+    }
+
+    public List<Representation> getRepresentations()
+    {
+        return List.copyOf(_representations);
+    }
+
+    @ManagedAttribute("Whether the first representation is produced when no representation is acceptable")
+    public boolean isFallbackToDefault()
+    {
+        return _fallbackToDefault;
+    }
+
+    public void setFallbackToDefault(boolean fallbackToDefault)
+    {
+        _fallbackToDefault = fallbackToDefault;
+    }
+
+    @ManagedAttribute("The number of requests for which no representation was acceptable")
+    public long getNotAcceptable()
+    {
+        return _notAcceptable.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void statsReset()
+    {
+        _notAcceptable.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        List<Representation> representations = getRepresentations();
+        if (baseRequest.isHandled() || representations.isEmpty())
+            return;
+
+        baseRequest.setHandled(true);
+        String vary = getVary(representations);
+        if (vary != null)
+            response.addHeader(HttpHeader.VARY.asString(), vary);
+
+        Representation representation = negotiate(baseRequest.getHttpFields(), representations);
+        if (representation == null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("No acceptable representation for {}", baseRequest);
+            if (!isFallbackToDefault())
+            {
+                _notAcceptable.increment();
+                response.sendError(HttpStatus.NOT_ACCEPTABLE_406);
+                return;
+            }
+            representation = representations.get(0);
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Negotiated {} for {}", representation, baseRequest);
+        baseRequest.setAttribute(REPRESENTATION_ATTRIBUTE, representation);
+        response.setContentType(representation.getContentType());
+        if (representation.getLanguage() != null)
+            response.setHeader(HttpHeader.CONTENT_LANGUAGE.asString(), representation.getLanguage());
+        if (representation.getEncoding() != null)
+            response.setHeader(HttpHeader.CONTENT_ENCODING.asString(), representation.getEncoding());
+        representation.getProducer().produce(representation, baseRequest, request, response);
+    }
+
+    /**
+     * <p>Selects the representation with the highest quality for the given request headers.</p>
+     *
+     * @param requestFields the request headers
+     * @param representations the available representations, in priority order
+     * @return the representation with the highest quality, or null if no representation is acceptable
+     */
+    public static Representation negotiate(HttpFields requestFields, List<Representation> representations)
+    {
+        Map<String, Double> mediaRanges = qualities(requestFields, HttpHeader.ACCEPT);
+        Map<String, Double> charsets = qualities(requestFields, HttpHeader.ACCEPT_CHARSET);
+        Map<String, Double> languages = qualities(requestFields, HttpHeader.ACCEPT_LANGUAGE);
+        Map<String, Double> encodings = qualities(requestFields, HttpHeader.ACCEPT_ENCODING);
+
+        Representation best = null;
+        double bestQuality = 0.0D;
+        for (Representation representation : representations)
+        {
+            double quality = representation.getQuality() *
+                mediaTypeQuality(mediaRanges, representation.getMediaType()) *
+                charsetQuality(charsets, representation.getCharset()) *
+                languageQuality(languages, representation.getLanguage()) *
+                encodingQuality(encodings, representation.getEncoding());
+            if (LOG.isDebugEnabled())
+                LOG.debug("Quality {} for {}", quality, representation);
+            if (quality > bestQuality)
+            {
+                best = representation;
+                bestQuality = quality;
+            }
+        }
+        return best;
+    }
+
+    /**
+     * @param representations the available representations
+     * @return the value of the {@code Vary} header for the given representations,
+     * or null if the representations do not differ
+     */
+    public static String getVary(List<Representation> representations)
+    {
+        List<String> vary = new ArrayList<>();
+        addVary(vary, representations, HttpHeader.ACCEPT, Representation::getMediaType);
+        addVary(vary, representations, HttpHeader.ACCEPT_CHARSET, Representation::getCharset);
+        addVary(vary, representations, HttpHeader.ACCEPT_LANGUAGE, Representation::getLanguage);
+        addVary(vary, representations, HttpHeader.ACCEPT_ENCODING, Representation::getEncoding);
+        return vary.isEmpty() ? null : String.join(", ", vary);
+    }
+
+    private static void addVary(List<String> vary, List<Representation> representations, HttpHeader header, Function<Representation, String> dimension)
+    {
+        Set<String> values = new HashSet<>();
+        for (Representation representation : representations)
+        {
+            String value = dimension.apply(representation);
+            values.add(value == null ? null : StringUtil.asciiToLowerCase(value));
+        }
+        if (values.size() > 1)
+            vary.add(header.asString());
+    }
+
+    private static Map<String, Double> qualities(HttpFields requestFields, HttpHeader header)
+    {
+        List<String> values = requestFields.getValuesList(header);
+        if (values.isEmpty())
+            return null;
+        QuotedQualityCSV csv = new QuotedQualityCSV();
+        values.forEach(csv::addValue);
+        Map<String, Double> qualities = new HashMap<>();
+        csv.getQualities().forEach((value, quality) -> qualities.putIfAbsent(StringUtil.asciiToLowerCase(value), quality));
+        return qualities;
+    }
+
+    private static double mediaTypeQuality(Map<String, Double> mediaRanges, String mediaType)
+    {
+        if (mediaRanges == null)
+            return 1.0D;
+        MediaType type = new MediaType(mediaType);
+        int specificity = -1;
+        double quality = 0.0D;
+        for (Map.Entry<String, Double> entry : mediaRanges.entrySet())
+        {
+            int s = type.match(new MediaType(entry.getKey()));
+            if (s > specificity)
+            {
+                specificity = s;
+                quality = entry.getValue();
+            }
+        }
+        return quality;
+    }
+
+    private static double charsetQuality(Map<String, Double> charsets, String charset)
+    {
+        if (charsets == null || charset == null)
+            return 1.0D;
+        Double quality = charsets.get(StringUtil.asciiToLowerCase(charset));
+        if (quality == null)
+            quality = charsets.get("*");
+        return quality == null ? 0.0D : quality;
+    }
+
+    private static double languageQuality(Map<String, Double> languages, String language)
+    {
+        if (languages == null || language == null)
+            return 1.0D;
+        // Basic filtering as specified by RFC 4647, section 3.3.1:
+        // the longest matching language range determines the quality.
+        String tag = StringUtil.asciiToLowerCase(language);
+        int specificity = -1;
+        double quality = 0.0D;
+        for (Map.Entry<String, Double> entry : languages.entrySet())
+        {
+            String range = entry.getKey();
+            int s;
+            if ("*".equals(range))
+                s = 0;
+            else if (tag.equals(range) || tag.startsWith(range + "-"))
+                s = range.length();
+            else
+                continue;
+            if (s > specificity)
+            {
+                specificity = s;
+                quality = entry.getValue();
+            }
+        }
+        return quality;
+    }
+
+    private static double encodingQuality(Map<String, Double> encodings, String encoding)
+    {
+        if (encodings == null)
+            return 1.0D;
+        String coding = encoding == null ? "identity" : StringUtil.asciiToLowerCase(encoding);
+        Double quality = encodings.get(coding);
+        if (quality == null)
+            quality = encodings.get("*");
+        if (quality == null)
+            // The identity coding is acceptable unless explicitly excluded.
+            return "identity".equals(coding) ? 1.0D : 0.0D;
+        return quality;
+    }
+
+    /**
+     * <p>Produces the content of a representation.</p>
+     */
+    @FunctionalInterface
+    public interface Producer
+    {
+        /**
+         * <p>Produces the content of the negotiated representation.</p>
+         * <p>The {@code Content-Type}, {@code Content-Language} and {@code Content-Encoding}
+         * response headers have already been set from the representation.</p>
+         *
+         * @param representation the negotiated representation
+         * @param baseRequest the Jetty request
+         * @param request the servlet request
+         * @param response the servlet response
+         * @throws IOException if the content cannot be written
+         * @throws ServletException if the content cannot be produced
+         */
+        void produce(Representation representation, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException;
+    }
+
+    /**
+     * <p>An available representation of a resource.</p>
+     */
+    public static class Representation
+    {
+        private final String _mediaType;
+        private final Producer _producer;
+        private String _charset;
+        private String _language;
+        private String _encoding;
+        private double _quality = 1.0D;
+
+        /**
+         * @param mediaType the media type, such as {@code text/html}, optionally with parameters
+         * other than the charset, such as {@code text/html;level=1}
+         * @param producer the producer of the content
+         */
+        public Representation(String mediaType, Producer producer)
+        {
+            _mediaType = Objects.requireNonNull(mediaType);
+            _producer = Objects.requireNonNull(producer);
+        }
+
+        public String getMediaType()
+        {
+            return _mediaType;
+        }
+
+        public Producer getProducer()
+        {
+            return _producer;
+        }
+
+        public String getCharset()
+        {
+            return _charset;
+        }
+
+        /**
+         * @param charset the charset of the content, or null if the content is not text
+         */
+        public void setCharset(String charset)
+        {
+            _charset = charset;
+        }
+
+        public String getLanguage()
+        {
+            return _language;
+        }
+
+        /**
+         * @param language the language tag of the content, such as {@code en-US}, or null
+         */
+        public void setLanguage(String language)
+        {
+            _language = language;
+        }
+
+        public String getEncoding()
+        {
+            return _encoding;
+        }
+
+        /**
+         * @param encoding the content coding, such as {@code gzip}, or null for the identity coding
+         */
+        public void setEncoding(String encoding)
+        {
+            _encoding = encoding;
+        }
+
+        /**
+         * @return the source quality of this representation, between 0 and 1
+         */
+        public double getQuality()
+        {
+            return _quality;
+        }
+
+        /**
+         * <p>Sets the source quality of this representation, that is the degradation
+         * of this representation with respect to the original resource.</p>
+         *
+         * @param quality the source quality, between 0 and 1
+         */
+        public void setQuality(double quality)
+        {
+            if (quality < 0.0D || quality > 1.0D)
+                throw new IllegalArgumentException("Invalid quality " + quality);
+            _quality = quality;
+        }
+
+        /**
+         * @return the value of the {@code Content-Type} header for this representation
+         */
+        public String getContentType()
+        {
+            return _charset == null ? _mediaType : _mediaType + ";charset=" + _charset;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s,lang=%s,enc=%s,q=%s}", getClass().getSimpleName(), hashCode(), getContentType(), getLanguage(), getEncoding(), getQuality());
+        }
+    }
+
+    private static class MediaType
+    {
+        private final String _type;
+        private final String _subType;
+        private final Map<String, String> _params = new HashMap<>();
+
+        private MediaType(String mediaType)
+        {
+            String[] parts = StringUtil.asciiToLowerCase(mediaType).split(";");
+            String fullType = parts[0].trim();
+            int slash = fullType.indexOf('/');
+            _type = slash < 0 ? fullType : fullType.substring(0, slash).trim();
+            _subType = slash < 0 ? "*" : fullType.substring(slash + 1).trim();
+            for (int i = 1; i < parts.length; ++i)
+            {
+                String param = parts[i].trim();
+                int equals = param.indexOf('=');
+                if (equals > 0)
+                    _params.put(param.substring(0, equals).trim(), QuotedStringTokenizer.unquote(param.substring(equals + 1).trim()));
+            }
+        }
+
+        /**
+         * @param range the media range
+         * @return the specificity of the match with the given range, or -1 if the range does not match
+         */
+        private int match(MediaType range)
+        {
+            if ("*".equals(range._type))
+                return 0;
+            if (!range._type.equals(_type))
+                return -1;
+            if ("*".equals(range._subType))
+                return 1;
+            if (!range._subType.equals(_subType))
+                return -1;
+            for (Map.Entry<String, String> param : range._params.entrySet())
+            {
+                if (!param.getValue().equals(_params.get(param.getKey())))
+                    return -1;
+            }
+            return 2 + range._params.size();
+        }
+    }
+}
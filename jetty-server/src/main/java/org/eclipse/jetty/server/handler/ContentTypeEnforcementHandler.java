@@ -0,0 +1,543 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.io.Writer;
+import java.nio.ByteBuffer;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.TypeUtil;
+import org.eclipse.jetty.util.Utf8Appendable;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that verifies that the response content matches the declared {@code Content-Type},
+ * to catch applications that mislabel their content.</p>
+ * <p>A {@link Validator} is configured for each checked mime type: by default the
+ * {@code image/png}, {@code image/jpeg}, {@code image/gif} and {@code image/webp} content is
+ * checked against the format magic bytes, while {@code application/json} and {@code text/*}
+ * content is checked to be valid UTF-8, unless a different charset is declared.
+ * Responses with other mime types, with a {@code Content-Encoding} or to {@code HEAD}
+ * requests are not checked.</p>
+ * <p>In {@link Mode#LOG} mode violations are only logged and counted.
+ * In {@link Mode#REJECT} mode the response is additionally replaced with an empty
+ * {@code 500} response: this is only possible if the violation is detected before the
+ * response is committed, otherwise the response is aborted.
+ * The magic bytes of a response are always checked before the response is committed,
+ * while invalid UTF-8 found after the first {@link org.eclipse.jetty.server.HttpConfiguration#getOutputBufferSize()
+ * output buffer} has been flushed aborts the response.</p>
+ * <p>Checked responses are also sent with {@code X-Content-Type-Options: nosniff}, so that
+ * clients honor the declared content type as the server does.</p>
+ * <p>This handler must be inside any {@link org.eclipse.jetty.server.handler.gzip.GzipHandler}
+ * so that it checks the content before it is compressed.</p>
+ */
+@ManagedObject("Content-Type enforcement handler")
+public class ContentTypeEnforcementHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ContentTypeEnforcementHandler.class);
+    public static final String X_CONTENT_TYPE_OPTIONS = "X-Content-Type-Options";
+
+    private final Map<String, Validator> _validators = new ConcurrentHashMap<>();
+    private final LongAdder _checked = new LongAdder();
+    private final LongAdder _violations = new LongAdder();
+    private final LongAdder _rejected = new LongAdder();
+    private Mode _mode = Mode.LOG;
+    private boolean _noSniff = true;
+
+    public ContentTypeEnforcementHandler()
+    {
+        putValidator("image/png", MagicValidator.of("89504E470D0A1A0A"));
+        putValidator("image/jpeg", MagicValidator.of("FFD8FF"));
+        putValidator("image/gif", MagicValidator.of("474946383761", "474946383961"));
+        putValidator("image/webp", MagicValidator.of("52494646????????57454250"));
+        putValidator("application/json", new Utf8Validator());
+        putValidator("text/*", new Utf8Validator());
+    }
+
+    @ManagedAttribute("What is done on violations, either LOG or REJECT")
+    public Mode getMode()
+    {
+        return _mode;
+    }
+
+    public void setMode(Mode mode)
+    {
+        _mode = mode;
+    }
+
+    /**
+     * @param mode the mode name, case insensitive
+     */
+    public void setMode(String mode)
+    {
+        setMode(Mode.valueOf(StringUtil.asciiToUpperCase(mode)));
+    }
+
+    @ManagedAttribute("Whether checked responses are sent with X-Content-Type-Options: nosniff")
+    public boolean isNoSniff()
+    {
+        return _noSniff;
+    }
+
+    public void setNoSniff(boolean noSniff)
+    {
+        _noSniff = noSniff;
+    }
+
+    /**
+     * @param mimeType the mime type, without parameters, such as {@code image/png},
+     * or a wildcard for all the subtypes of a type, such as {@code text/*}
+     * @param validator the validator of the content of that mime type
+     */
+    public void putValidator(String mimeType, Validator validator)
+    {
+        _validators.put(StringUtil.asciiToLowerCase(mimeType), validator);
+    }
+
+    /**
+     * @param mimeType a mime type, or mime type wildcard
+     * @return the validator that was removed, or {@code null} if there was none
+     */
+    public Validator removeValidator(String mimeType)
+    {
+        return _validators.remove(StringUtil.asciiToLowerCase(mimeType));
+    }
+
+    /**
+     * @param mimeType the mime type, without parameters
+     * @return the validator for the given mime type, or {@code null} if it is not checked
+     */
+    public Validator getValidator(String mimeType)
+    {
+        if (mimeType == null)
+            return null;
+        mimeType = StringUtil.asciiToLowerCase(mimeType);
+        Validator validator = _validators.get(mimeType);
+        if (validator == null)
+        {
+            int slash = mimeType.indexOf('/');
+            if (slash > 0)
+                validator = _validators.get(mimeType.substring(0, slash) + "/*");
+        }
+        return validator;
+    }
+
+    @ManagedAttribute("The number of responses checked")
+    public long getChecked()
+    {
+        return _checked.sum();
+    }
+
+    @ManagedAttribute("The number of responses that did not match their Content-Type")
+    public long getViolations()
+    {
+        return _violations.sum();
+    }
+
+    @ManagedAttribute("The number of responses replaced or aborted because of a violation")
+    public long getRejected()
+    {
+        return _rejected.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void statsReset()
+    {
+        _checked.reset();
+        _violations.reset();
+        _rejected.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        if (!baseRequest.isHead() && !isIntercepted(out))
+            out.setInterceptor(new EnforcementInterceptor(baseRequest, out.getInterceptor()));
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private boolean isIntercepted(HttpOutput out)
+    {
+        HttpOutput.Interceptor interceptor = out.getInterceptor();
+        while (interceptor != null)
+        {
+            if (interceptor instanceof EnforcementInterceptor && ((EnforcementInterceptor)interceptor).getHandler() == this)
+                return true;
+            interceptor = interceptor.getNextInterceptor();
+        }
+        return false;
+    }
+
+    /**
+     * <p>Creates the validation of the content of the given response, just before its first content is written.</p>
+     *
+     * @param request the request
+     * @param response the response
+     * @return the validation of the response content, or {@code null} if the response is not checked
+     */
+    protected Validation newValidation(Request request, Response response)
+    {
+        if (HttpStatus.hasNoBody(response.getStatus()))
+            return null;
+        if (response.getHttpFields().contains(HttpHeader.CONTENT_ENCODING))
+            return null;
+        String contentType = response.getContentType();
+        if (contentType == null)
+            return null;
+        Validator validator = getValidator(MimeTypes.getContentTypeWithoutCharset(contentType));
+        return validator == null ? null : validator.newValidation(contentType);
+    }
+
+    /**
+     * <p>Callback method invoked when the content of a response does not match its {@code Content-Type}.</p>
+     *
+     * @param request the request
+     * @param violation the description of the violation
+     */
+    protected void onViolation(Request request, String violation)
+    {
+        LOG.warn("Content-Type {} violation for {} {}: {}", request.getResponse().getContentType(), request.getMethod(), request.getRequestURI(), violation);
+    }
+
+    /**
+     * <p>What is done when the content of a response does not match its {@code Content-Type}.</p>
+     */
+    public enum Mode
+    {
+        /**
+         * The violation is only logged.
+         */
+        LOG,
+        /**
+         * The violation is logged and the response is replaced with a 500 response, or aborted if already committed.
+         */
+        REJECT
+    }
+
+    /**
+     * <p>The validator of the content of a mime type.</p>
+     */
+    public interface Validator
+    {
+        /**
+         * @param contentType the full response content type, including the parameters
+         * @return a new validation of the content of a single response, or {@code null} to not check the response
+         */
+        Validation newValidation(String contentType);
+    }
+
+    /**
+     * <p>The validation of the content of a single response.</p>
+     */
+    public interface Validation
+    {
+        /**
+         * <p>Content is not written until either this many bytes are available
+         * or the response is complete, so that the first call to
+         * {@link #validate(ByteBuffer, boolean)} can check the beginning of
+         * the content before the response is committed.</p>
+         *
+         * @return the number of leading bytes needed to start the validation
+         */
+        default int getHeadLength()
+        {
+            return 0;
+        }
+
+        /**
+         * <p>Validates the next content of the response.</p>
+         *
+         * @param content the next content, whose position must not be modified
+         * @param last whether this is the last content of the response
+         * @return the description of the violation, or {@code null} if the content is valid so far
+         */
+        String validate(ByteBuffer content, boolean last);
+    }
+
+    /**
+     * <p>A {@link Validator} that checks that the content starts with one of the given signatures.</p>
+     */
+    public static class MagicValidator implements Validator
+    {
+        private final int[][] _signatures;
+        private final int _length;
+
+        /**
+         * @param signatures the signatures, with {@code -1} elements matching any byte
+         */
+        public MagicValidator(int[]... signatures)
+        {
+            _signatures = signatures;
+            int length = 0;
+            for (int[] signature : signatures)
+            {
+                length = Math.max(length, signature.length);
+            }
+            _length = length;
+        }
+
+        /**
+         * @param signatures the signatures in hexadecimal, with {@code ??} matching any byte
+         * @return a new validator for the given signatures
+         */
+        public static MagicValidator of(String... signatures)
+        {
+            int[][] parsed = new int[signatures.length][];
+            for (int s = 0; s < signatures.length; s++)
+            {
+                String hex = signatures[s];
+                if (hex.length() % 2 != 0)
+                    throw new IllegalArgumentException("Invalid signature " + hex);
+                int[] signature = new int[hex.length() / 2];
+                for (int i = 0; i < signature.length; i++)
+                {
+                    String digits = hex.substring(2 * i, 2 * i + 2);
+                    signature[i] = "??".equals(digits) ? -1 : TypeUtil.parseInt(digits, 0, 2, 16);
+                }
+                parsed[s] = signature;
+            }
+            return new MagicValidator(parsed);
+        }
+
+        @Override
+        public Validation newValidation(String contentType)
+        {
+            return new Validation()
+            {
+                private boolean _validated;
+
+                @Override
+                public int getHeadLength()
+                {
+                    return _length;
+                }
+
+                @Override
+                public String validate(ByteBuffer content, boolean last)
+                {
+                    if (_validated)
+                        return null;
+                    _validated = true;
+                    for (int[] signature : _signatures)
+                    {
+                        if (matches(signature, content))
+                            return null;
+                    }
+                    return "no magic bytes in " + BufferUtil.toHexSummary(content);
+                }
+            };
+        }
+
+        private static boolean matches(int[] signature, ByteBuffer content)
+        {
+            if (content.remaining() < signature.length)
+                return false;
+            int position = content.position();
+            for (int i = 0; i < signature.length; i++)
+            {
+                if (signature[i] >= 0 && signature[i] != (content.get(position + i) & 0xFF))
+                    return false;
+            }
+            return true;
+        }
+    }
+
+    /**
+     * <p>A {@link Validator} that checks that textual content is valid UTF-8.</p>
+     * <p>Responses that declare a charset other than UTF-8 are not checked.</p>
+     */
+    public static class Utf8Validator implements Validator
+    {
+        @Override
+        public Validation newValidation(String contentType)
+        {
+            String charset = MimeTypes.getCharsetFromContentType(contentType);
+            if (charset != null && !StringUtil.__UTF8.equalsIgnoreCase(charset))
+                return null;
+
+            Utf8Appendable utf8 = new Utf8Appendable(Writer.nullWriter())
+            {
+                @Override
+                public int length()
+                {
+                    return 0;
+                }
+
+                @Override
+                public String getPartialString()
+                {
+                    return "";
+                }
+            };
+
+            return (content, last) ->
+            {
+                try
+                {
+                    utf8.append(content.slice());
+                    if (last)
+                        utf8.checkState();
+                    return null;
+                }
+                catch (Utf8Appendable.NotUtf8Exception x)
+                {
+                    return x.getMessage();
+                }
+            };
+        }
+    }
+
+    private class EnforcementInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request _request;
+        private final HttpOutput.Interceptor _next;
+        private boolean _started;
+        private Validation _validation;
+        private ByteBuffer _head;
+        private boolean _replacing;
+
+        private EnforcementInterceptor(Request request, HttpOutput.Interceptor next)
+        {
+            _request = request;
+            _next = next;
+        }
+
+        private ContentTypeEnforcementHandler getHandler()
+        {
+            return ContentTypeEnforcementHandler.this;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        @Override
+        public void resetBuffer()
+        {
+            _started = false;
+            _validation = null;
+            _head = null;
+            HttpOutput.Interceptor.super.resetBuffer();
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            if (_replacing)
+            {
+                // Discard the content of the response that is being replaced.
+                if (last)
+                    _next.write(BufferUtil.EMPTY_BUFFER, true, callback);
+                else
+                    callback.succeeded();
+                return;
+            }
+
+            if (!_started)
+            {
+                _started = true;
+                Response response = _request.getResponse();
+                _validation = response.isCommitted() ? null : newValidation(_request, response);
+                if (_validation != null)
+                {
+                    _checked.increment();
+                    if (isNoSniff())
+                        response.setHeader(X_CONTENT_TYPE_OPTIONS, "nosniff");
+                }
+            }
+
+            if (_validation == null)
+            {
+                _next.write(content, last, callback);
+                return;
+            }
+
+            // Hold the content back until the head of the content can be validated.
+            int headLength = _validation.getHeadLength();
+            if (_head != null || (BufferUtil.length(content) < headLength && !last))
+            {
+                // Copy the content, as the application may reuse its buffer when the callback is completed.
+                ByteBuffer head = BufferUtil.allocate(BufferUtil.length(_head) + BufferUtil.length(content));
+                if (_head != null)
+                    BufferUtil.append(head, _head);
+                if (content != null)
+                    BufferUtil.append(head, content);
+                _head = null;
+                if (head.remaining() < headLength && !last)
+                {
+                    _head = head;
+                    callback.succeeded();
+                    return;
+                }
+                content = head;
+            }
+
+            String violation = _validation.validate(content, last);
+            if (violation == null)
+            {
+                _next.write(content, last, callback);
+                return;
+            }
+
+            _validation = null;
+            _violations.increment();
+            onViolation(_request, violation);
+            if (getMode() == Mode.LOG)
+            {
+                _next.write(content, last, callback);
+                return;
+            }
+
+            _rejected.increment();
+            Response response = _request.getResponse();
+            if (response.isCommitted())
+            {
+                callback.failed(new IOException("Content-Type violation: " + violation));
+                return;
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Replacing response to {} with 500", _request);
+            response.resetContent();
+            response.setStatus(HttpStatus.INTERNAL_SERVER_ERROR_500);
+            _replacing = true;
+            if (last)
+                _next.write(BufferUtil.EMPTY_BUFFER, true, callback);
+            else
+                callback.succeeded();
+        }
+    }
+}
@@ -77,6 +77,7 @@ import org.eclipse.jetty.util.AttributesMap;
 import org.eclipse.jetty.util.Index;
 import org.eclipse.jetty.util.Loader;
 import org.eclipse.jetty.util.MultiException;
+import org.eclipse.jetty.util.ParameterParser;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
@@ -101,7 +102,9 @@ import org.slf4j.LoggerFactory;
  * </p>
  * <p>
  * The maximum size of a form that can be processed by this context is controlled by the system properties {@code org.eclipse.jetty.server.Request.maxFormKeys} and
- * {@code org.eclipse.jetty.server.Request.maxFormContentSize}. These can also be configured with {@link #setMaxFormContentSize(int)} and {@link #setMaxFormKeys(int)}
+ * {@code org.eclipse.jetty.server.Request.maxFormContentSize}. These can also be configured with {@link #setMaxFormContentSize(int)} and {@link #setMaxFormKeys(int)}.
+ * The maximum length of a form value is controlled by the system property {@code org.eclipse.jetty.server.Request.maxFormValueLength} or with
+ * {@link #setMaxFormValueLength(int)}. Query and form parameters are parsed with the {@link ParameterParser} returned by {@link #newParameterParser()}.
  * </p>
  * <p>
  * The executor is made available via a context attributed {@code org.eclipse.jetty.server.Executor}.
@@ -143,6 +146,7 @@ public class ContextHandler extends ScopedHandler implements Attributes, Gracefu
 
     public static final String MAX_FORM_KEYS_KEY = "org.eclipse.jetty.server.Request.maxFormKeys";
     public static final String MAX_FORM_CONTENT_SIZE_KEY = "org.eclipse.jetty.server.Request.maxFormContentSize";
+    public static final String MAX_FORM_VALUE_LENGTH_KEY = "org.eclipse.jetty.server.Request.maxFormValueLength";
     public static final int DEFAULT_MAX_FORM_KEYS = 1000;
     public static final int DEFAULT_MAX_FORM_CONTENT_SIZE = 200000;
 
@@ -217,6 +221,9 @@ public class ContextHandler extends ScopedHandler implements Attributes, Gracefu
     private boolean _allowNullPathInfo;
     private int _maxFormKeys = Integer.getInteger(MAX_FORM_KEYS_KEY, DEFAULT_MAX_FORM_KEYS);
     private int _maxFormContentSize = Integer.getInteger(MAX_FORM_CONTENT_SIZE_KEY, DEFAULT_MAX_FORM_CONTENT_SIZE);
+    private int _maxFormValueLength = Integer.getInteger(MAX_FORM_VALUE_LENGTH_KEY, -1);
+    private ParameterParser.Decoding _parameterDecoding = ParameterParser.Decoding.REPLACE;
+    private ParameterParser.ViolationListener _parameterViolationListener;
     private boolean _compactPath = false;
     private boolean _usingSecurityManager = getSecurityManager() != null;
 
@@ -1818,6 +1825,69 @@ public class ContextHandler extends ScopedHandler implements Attributes, Gracefu
         _maxFormKeys = max;
     }
 
+    @ManagedAttribute("The maximum length of a form value")
+    public int getMaxFormValueLength()
+    {
+        return _maxFormValueLength;
+    }
+
+    /**
+     * Set the maximum length of a decoded form or query parameter value.
+     *
+     * @param maxLength the maximum length of a parameter value, or -1 for no limit
+     */
+    public void setMaxFormValueLength(int maxLength)
+    {
+        _maxFormValueLength = maxLength;
+    }
+
+    @ManagedAttribute("The decoding mode of form and query parameters")
+    public ParameterParser.Decoding getParameterDecoding()
+    {
+        return _parameterDecoding;
+    }
+
+    /**
+     * Set how invalid percent-encoded sequences and invalid charset byte sequences
+     * of form and query parameters are treated.
+     *
+     * @param decoding the decoding mode
+     */
+    public void setParameterDecoding(ParameterParser.Decoding decoding)
+    {
+        _parameterDecoding = decoding == null ? ParameterParser.Decoding.REPLACE : decoding;
+    }
+
+    public ParameterParser.ViolationListener getParameterViolationListener()
+    {
+        return _parameterViolationListener;
+    }
+
+    /**
+     * Set the listener of form and query parameter violations, that decides whether
+     * a violation is tolerated or the request fails with a 400 response.
+     *
+     * @param listener the listener of violations, or null to fail on any violation
+     */
+    public void setParameterViolationListener(ParameterParser.ViolationListener listener)
+    {
+        _parameterViolationListener = listener;
+    }
+
+    /**
+     * @return a new {@link ParameterParser} configured with the form limits of this context
+     */
+    public ParameterParser newParameterParser()
+    {
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxKeys(getMaxFormKeys());
+        parser.setMaxLength(getMaxFormContentSize());
+        parser.setMaxValueLength(getMaxFormValueLength());
+        parser.setDecoding(getParameterDecoding());
+        parser.setViolationListener(getParameterViolationListener());
+        return parser;
+    }
+
     /**
      * @return True if URLs are compacted to replace multiple '/'s with a single '/'
      * @deprecated use {@code CompactPathRule} with {@code RewriteHandler} instead.
@@ -27,6 +27,7 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.server.Handler;
 import org.eclipse.jetty.server.HandlerContainer;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.HttpChannelState;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.util.ArrayUtil;
@@ -202,7 +203,7 @@ public class ContextHandlerCollection extends HandlerCollection
         {
             for (Handler handler : handlers)
             {
-                handler.handle(target, baseRequest, request, response);
+                HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
                 if (baseRequest.isHandled())
                     return;
             }
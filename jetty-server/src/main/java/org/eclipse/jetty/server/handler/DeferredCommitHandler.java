@@ -0,0 +1,371 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.security.MessageDigest;
+import java.security.NoSuchAlgorithmException;
+import java.util.Base64;
+import java.util.EventListener;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import javax.servlet.ServletException;
+import javax.servlet.ServletRequest;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.HttpOutput.Interceptor;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.StringUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>
+ * A Handler that holds back the first {@link #getCommitWindow() commit window} bytes
+ * of the response content, so that the response is not committed until either the
+ * content exceeds the window or the response is complete.
+ * While the commit is deferred, the wrapped handlers and their callers may still change
+ * the response status and headers, for example to map a failure that happened after
+ * some content was written to an error page, since resetting the buffer discards the
+ * held content.
+ * </p>
+ * <p>
+ * When the complete response fits in the window, it is written in a single last write,
+ * so that the response has a {@code Content-Length} header rather than being chunked,
+ * and, if {@link #isETagGenerated() enabled}, an {@code ETag} header computed from a
+ * hash of the content.
+ * {@link Listener}s are notified just before the commit, with the held content,
+ * and may change the status and headers too.
+ * </p>
+ * <p>
+ * Unlike {@link BufferedResponseHandler}, the memory held per response is bounded by
+ * the window: once the content exceeds the window, the held content is written and
+ * the response is streamed as usual.
+ * Note that {@link HttpServletResponse#flushBuffer()} does not commit while the
+ * content fits in the window; streaming responses that must reach the client
+ * promptly can use {@link #flush(ServletRequest)}, or can be excluded by mime type
+ * (by default, {@code text/event-stream} is excluded).
+ * </p>
+ */
+public class DeferredCommitHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(DeferredCommitHandler.class);
+
+    private final IncludeExclude<String> _mimeTypes = new IncludeExclude<>();
+    private final List<Listener> _listeners = new CopyOnWriteArrayList<>();
+    private int _commitWindow = 8192;
+    private boolean _etagGenerated;
+
+    public DeferredCommitHandler()
+    {
+        _mimeTypes.exclude("text/event-stream");
+    }
+
+    /**
+     * @return the max number of content bytes held before the response is committed
+     */
+    public int getCommitWindow()
+    {
+        return _commitWindow;
+    }
+
+    /**
+     * @param commitWindow the max number of content bytes held before the response is committed
+     */
+    public void setCommitWindow(int commitWindow)
+    {
+        _commitWindow = commitWindow;
+    }
+
+    /**
+     * @return whether an {@code ETag} is generated for complete responses that fit in the window
+     */
+    public boolean isETagGenerated()
+    {
+        return _etagGenerated;
+    }
+
+    /**
+     * <p>Sets whether a strong {@code ETag} is generated from a hash of the content
+     * of the {@code 200} responses that fit in the window and have no {@code ETag}.</p>
+     *
+     * @param etagGenerated whether an {@code ETag} is generated
+     */
+    public void setETagGenerated(boolean etagGenerated)
+    {
+        _etagGenerated = etagGenerated;
+    }
+
+    /**
+     * @return the mime types of the responses whose commit is deferred,
+     * evaluated at the first write
+     */
+    public IncludeExclude<String> getMimeIncludeExclude()
+    {
+        return _mimeTypes;
+    }
+
+    public void addListener(Listener listener)
+    {
+        _listeners.add(listener);
+    }
+
+    public boolean removeListener(Listener listener)
+    {
+        return _listeners.remove(listener);
+    }
+
+    /**
+     * <p>Stops deferring the commit of the response of the given request
+     * and flushes the response, committing it.</p>
+     * <p>This is the escape hatch for responses that must reach the client
+     * before the content exceeds the window, for example the first events of
+     * a long poll. It must not be called if the output is in async mode.</p>
+     *
+     * @param request the request whose response must be flushed
+     * @throws IOException if the flush fails
+     */
+    public static void flush(ServletRequest request) throws IOException
+    {
+        Request baseRequest = Request.getBaseRequest(request);
+        if (baseRequest == null)
+            return;
+        Response response = baseRequest.getResponse();
+        for (Interceptor i = response.getHttpOutput().getInterceptor(); i != null; i = i.getNextInterceptor())
+        {
+            if (i instanceof DeferredCommitInterceptor)
+                ((DeferredCommitInterceptor)i).release();
+        }
+        response.flushBuffer();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        if (getCommitWindow() > 0 && !isIntercepting(out))
+            out.setInterceptor(new DeferredCommitInterceptor(baseRequest, out.getInterceptor()));
+        else if (LOG.isDebugEnabled())
+            LOG.debug("{} not deferring commit of {}", this, request);
+        if (_handler != null)
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
+    }
+
+    private boolean isIntercepting(HttpOutput out)
+    {
+        for (Interceptor i = out.getInterceptor(); i != null; i = i.getNextInterceptor())
+        {
+            if (i instanceof DeferredCommitInterceptor)
+                return true;
+        }
+        return false;
+    }
+
+    protected boolean isMimeTypeDeferrable(String mimeType)
+    {
+        if (mimeType == null)
+            return true;
+        mimeType = MimeTypes.getContentTypeWithoutCharset(mimeType);
+        return _mimeTypes.test(StringUtil.asciiToLowerCase(mimeType));
+    }
+
+    private void notifyCommit(Request request, ByteBuffer content, boolean complete)
+    {
+        for (Listener listener : _listeners)
+        {
+            try
+            {
+                listener.onCommit(request, request.getResponse(), content.asReadOnlyBuffer(), complete);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Exception while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    /**
+     * <p>Listener notified just before a deferred response is committed.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked just before the response is committed,
+         * when its status and headers can still be changed.</p>
+         *
+         * @param request the request
+         * @param response the response about to be committed
+         * @param content the held content, read-only
+         * @param complete whether the held content is the complete response content
+         */
+        void onCommit(HttpServletRequest request, HttpServletResponse response, ByteBuffer content, boolean complete);
+    }
+
+    private class DeferredCommitInterceptor implements Interceptor
+    {
+        private final Request _request;
+        private final Interceptor _next;
+        private final ByteBufferPool _bufferPool;
+        private final MessageDigest _digest;
+        private ByteBuffer _held;
+        private Boolean _deferring;
+        private volatile boolean _released;
+
+        private DeferredCommitInterceptor(Request request, Interceptor next)
+        {
+            _request = request;
+            _next = next;
+            _bufferPool = request.getHttpChannel().getByteBufferPool();
+            _digest = isETagGenerated() ? newMessageDigest() : null;
+        }
+
+        @Override
+        public Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        private void release()
+        {
+            _released = true;
+        }
+
+        @Override
+        public void resetBuffer()
+        {
+            if (_held != null)
+            {
+                _bufferPool.release(_held);
+                _held = null;
+            }
+            if (_digest != null)
+                _digest.reset();
+            // The decision is taken again at the next write, maybe with a different content type.
+            if (Boolean.TRUE.equals(_deferring))
+                _deferring = null;
+            Interceptor.super.resetBuffer();
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} write last={} {}", this, last, BufferUtil.toDetailString(content));
+
+            if (_deferring == null)
+                _deferring = isMimeTypeDeferrable(_request.getResponse().getContentType());
+
+            if (!_deferring)
+            {
+                _next.write(content, last, callback);
+                return;
+            }
+
+            int length = BufferUtil.length(content);
+            if (!_released && BufferUtil.length(_held) + length <= getCommitWindow())
+            {
+                hold(content);
+                if (last)
+                    commit(BufferUtil.EMPTY_BUFFER, true, true, callback);
+                else
+                    callback.succeeded();
+            }
+            else
+            {
+                commit(content, last, false, callback);
+            }
+        }
+
+        private void hold(ByteBuffer content)
+        {
+            if (!BufferUtil.hasContent(content))
+                return;
+            if (_held == null)
+                _held = _bufferPool.acquire(getCommitWindow(), false);
+            if (_digest != null)
+                _digest.update(content.slice());
+            BufferUtil.append(_held, content);
+        }
+
+        private void commit(ByteBuffer content, boolean last, boolean complete, Callback callback)
+        {
+            _deferring = false;
+            ByteBuffer held = _held;
+            _held = null;
+
+            if (complete && _digest != null)
+                generateETag();
+            notifyCommit(_request, held == null ? BufferUtil.EMPTY_BUFFER : held, complete);
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} committing {} held bytes, complete={}", this, BufferUtil.length(held), complete);
+
+            if (held == null)
+            {
+                _next.write(content, last, callback);
+            }
+            else if (!BufferUtil.hasContent(content))
+            {
+                _next.write(held, last, Callback.from(callback, () -> _bufferPool.release(held)));
+            }
+            else
+            {
+                _next.write(held, false, Callback.from(() ->
+                {
+                    _bufferPool.release(held);
+                    _next.write(content, last, callback);
+                }, callback::failed));
+            }
+        }
+
+        private void generateETag()
+        {
+            Response response = _request.getResponse();
+            if (response.getStatus() != HttpStatus.OK_200 || response.getHttpFields().contains(HttpHeader.ETAG))
+                return;
+            String hash = Base64.getUrlEncoder().withoutPadding().encodeToString(_digest.digest());
+            response.setHeader(HttpHeader.ETAG.asString(), "\"" + hash + "\"");
+        }
+
+        private MessageDigest newMessageDigest()
+        {
+            try
+            {
+                return MessageDigest.getInstance("SHA-256");
+            }
+            catch (NoSuchAlgorithmException x)
+            {
+                throw new IllegalStateException(x);
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{held=%d,deferring=%s}", getClass().getSimpleName(), hashCode(), BufferUtil.length(_held), _deferring);
+        }
+    }
+}
@@ -22,9 +22,14 @@ import java.nio.BufferOverflowException;
 import java.nio.ByteBuffer;
 import java.nio.charset.Charset;
 import java.nio.charset.StandardCharsets;
+import java.util.Arrays;
+import java.util.Collection;
 import java.util.HashMap;
+import java.util.LinkedHashMap;
 import java.util.List;
 import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.stream.Collectors;
 import javax.servlet.RequestDispatcher;
 import javax.servlet.ServletException;
@@ -60,12 +65,19 @@ public class ErrorHandler extends AbstractHandler
     public static final String ERROR_PAGE = "org.eclipse.jetty.server.error_page";
     public static final String ERROR_CONTEXT = "org.eclipse.jetty.server.error_context";
     public static final String ERROR_CHARSET = "org.eclipse.jetty.server.error_charset";
+    /**
+     * The media type of RFC 9457 problem details.
+     */
+    public static final String PROBLEM_JSON = "application/problem+json";
 
     boolean _showServlet = true;
     boolean _showStacks = true;
     boolean _disableStacks = false;
     boolean _showMessageInTitle = true;
     String _cacheControl = "must-revalidate,no-cache,no-store";
+    boolean _problemDetails = false;
+    boolean _redactServerErrors = false;
+    final List<ProblemMapper> _problemMappers = new CopyOnWriteArrayList<>();
 
     public ErrorHandler()
     {
@@ -148,7 +160,8 @@ public class ErrorHandler extends AbstractHandler
 
         if (acceptable.isEmpty() && !baseRequest.getHttpFields().contains(HttpHeader.ACCEPT))
         {
-            generateAcceptableResponse(baseRequest, request, response, code, message, MimeTypes.Type.TEXT_HTML.asString());
+            String contentType = isProblemDetails() ? PROBLEM_JSON : MimeTypes.Type.TEXT_HTML.asString();
+            generateAcceptableResponse(baseRequest, request, response, code, message, contentType);
         }
         else
         {
@@ -214,7 +227,9 @@ public class ErrorHandler extends AbstractHandler
      * <code>Accept</code> header, until {@link Request#isHandled()} is true and a
      * response of the appropriate type is generated.
      * </p>
-     * <p>The default implementation handles "text/html", "text/*" and "*&#47;*".
+     * <p>The default implementation handles "text/html", "text/*" and "*&#47;*",
+     * the JSON types and {@value #PROBLEM_JSON}; when {@link #isProblemDetails()}
+     * is true, "*&#47;*" and the JSON types are served as {@value #PROBLEM_JSON}.
      * The method can be overridden to handle other types.  Implementations must
      * immediate produce a response and may not be async.
      * </p>
@@ -257,7 +272,22 @@ public class ErrorHandler extends AbstractHandler
                 return;
         }
 
+        if (isProblemDetails())
+        {
+            switch (contentType)
+            {
+                case "*/*":
+                case "text/json":
+                case "application/json":
+                    contentType = PROBLEM_JSON;
+                    break;
+                default:
+                    break;
+            }
+        }
+
         MimeTypes.Type type;
+        boolean problem = false;
         switch (contentType)
         {
             case "text/html":
@@ -275,6 +305,13 @@ public class ErrorHandler extends AbstractHandler
                     charset = StandardCharsets.UTF_8;
                 break;
 
+            case PROBLEM_JSON:
+                type = MimeTypes.Type.TEXT_JSON;
+                problem = true;
+                if (charset == null)
+                    charset = StandardCharsets.UTF_8;
+                break;
+
             case "text/plain":
                 type = MimeTypes.Type.TEXT_PLAIN;
                 if (charset == null)
@@ -307,8 +344,17 @@ public class ErrorHandler extends AbstractHandler
                         handleErrorPage(request, writer, code, message);
                         break;
                     case TEXT_JSON:
-                        response.setContentType(contentType);
-                        writeErrorJson(request, writer, code, message);
+                        if (problem)
+                        {
+                            response.setContentType(PROBLEM_JSON);
+                            response.setCharacterEncoding(charset.name());
+                            writeProblemDetails(request, writer, newProblemDetails(request, code, message));
+                        }
+                        else
+                        {
+                            response.setContentType(contentType);
+                            writeErrorJson(request, writer, code, message);
+                        }
                         break;
                     case TEXT_PLAIN:
                         response.setContentType(MimeTypes.Type.TEXT_PLAIN.asString());
@@ -492,6 +538,144 @@ public class ErrorHandler extends AbstractHandler
                 .collect(Collectors.joining(",\n", "{\n", "\n}")));
     }
 
+    /**
+     * <p>Creates the problem details of an error response.</p>
+     * <p>The {@link ProblemMapper}s are asked to map the error exception and its
+     * causes, in order; if none does, a problem of type {@code about:blank} is
+     * created. The title, detail and instance members are then defaulted
+     * to the status reason, the error message and the request URI.</p>
+     * <p>Unless the details of server errors are redacted, the servlet name and,
+     * if {@link #isShowStacks()}, the stack trace are added as the {@code servlet}
+     * and {@code stackTrace} extension members.</p>
+     *
+     * @param request The servlet request
+     * @param code the http error code
+     * @param message the http error message (may be null)
+     * @return the problem details
+     */
+    protected ProblemDetails newProblemDetails(HttpServletRequest request, int code, String message)
+    {
+        Throwable failure = (Throwable)request.getAttribute(Dispatcher.ERROR_EXCEPTION);
+        ProblemDetails problem = mapProblem(request, code, failure);
+        boolean redact = isRedactServerErrors() && HttpStatus.isServerError(code);
+        boolean mapped = problem != null;
+        if (!mapped)
+            problem = new ProblemDetails();
+        problem.setStatus(code);
+        if (problem.getTitle() == null)
+            problem.setTitle(HttpStatus.getMessage(code));
+        if (problem.getDetail() == null && !redact && message != null && !message.equals(problem.getTitle()))
+            problem.setDetail(message);
+        if (problem.getInstance() == null)
+            problem.setInstance(request.getRequestURI());
+
+        if (!redact)
+        {
+            Object servlet = request.getAttribute(Dispatcher.ERROR_SERVLET_NAME);
+            if (isShowServlet() && servlet != null)
+                problem.getExtensions().putIfAbsent("servlet", servlet.toString());
+            if (failure != null && isShowStacks() && !_disableStacks)
+            {
+                StringWriter stack = new StringWriter();
+                try (PrintWriter printer = new PrintWriter(stack))
+                {
+                    failure.printStackTrace(printer);
+                }
+                problem.getExtensions().putIfAbsent("stackTrace", stack.toString());
+            }
+        }
+        return problem;
+    }
+
+    private ProblemDetails mapProblem(HttpServletRequest request, int code, Throwable failure)
+    {
+        if (_problemMappers.isEmpty())
+            return null;
+        Throwable cause = failure;
+        while (true)
+        {
+            for (ProblemMapper mapper : _problemMappers)
+            {
+                try
+                {
+                    ProblemDetails problem = mapper.map(request, code, cause);
+                    if (problem != null)
+                        return problem;
+                }
+                catch (Throwable x)
+                {
+                    LOG.warn("Unable to map problem details with {}", mapper, x);
+                }
+            }
+            if (cause == null)
+                return null;
+            cause = cause.getCause();
+        }
+    }
+
+    /**
+     * <p>Writes the given problem details as a JSON object.</p>
+     *
+     * @param request The servlet request
+     * @param writer the writer of the error response content
+     * @param problem the problem details to write
+     */
+    protected void writeProblemDetails(HttpServletRequest request, PrintWriter writer, ProblemDetails problem)
+    {
+        Map<String, Object> json = new LinkedHashMap<>();
+        json.put("type", problem.getType());
+        json.put("title", problem.getTitle());
+        json.put("status", problem.getStatus());
+        if (problem.getDetail() != null)
+            json.put("detail", problem.getDetail());
+        if (problem.getInstance() != null)
+            json.put("instance", problem.getInstance());
+        problem.getExtensions().forEach(json::putIfAbsent);
+        appendJson(writer, json);
+    }
+
+    private static void appendJson(PrintWriter writer, Object value)
+    {
+        if (value == null)
+        {
+            writer.append("null");
+        }
+        else if (value instanceof Boolean || value instanceof Number)
+        {
+            writer.append(value.toString());
+        }
+        else if (value instanceof Map)
+        {
+            writer.append('{');
+            String separator = "";
+            for (Map.Entry<?, ?> entry : ((Map<?, ?>)value).entrySet())
+            {
+                writer.append(separator);
+                QuotedStringTokenizer.quote(writer, String.valueOf(entry.getKey()));
+                writer.append(':');
+                appendJson(writer, entry.getValue());
+                separator = ",";
+            }
+            writer.append('}');
+        }
+        else if (value instanceof Collection || value instanceof Object[])
+        {
+            writer.append('[');
+            String separator = "";
+            for (Object item : value instanceof Collection ? (Collection<?>)value : Arrays.asList((Object[])value))
+            {
+                writer.append(separator);
+                appendJson(writer, item);
+                separator = ",";
+            }
+            writer.append(']');
+        }
+        else
+        {
+            QuotedStringTokenizer.quote(writer, value.toString());
+        }
+    }
+
     protected void writeErrorPageStacks(HttpServletRequest request, Writer writer)
         throws IOException
     {
@@ -530,6 +714,22 @@ public class ErrorHandler extends AbstractHandler
             reason = HttpStatus.getMessage(status);
         if (HttpStatus.hasNoBody(status))
             return BufferUtil.EMPTY_BUFFER;
+        if (isProblemDetails())
+        {
+            StringWriter content = new StringWriter();
+            Map<String, Object> json = new LinkedHashMap<>();
+            json.put("type", "about:blank");
+            json.put("title", HttpStatus.getMessage(status));
+            json.put("status", status);
+            if (!reason.equals(HttpStatus.getMessage(status)))
+                json.put("detail", reason);
+            try (PrintWriter writer = new PrintWriter(content))
+            {
+                appendJson(writer, json);
+            }
+            fields.put(HttpHeader.CONTENT_TYPE, PROBLEM_JSON + ";charset=utf-8");
+            return BufferUtil.toBuffer(content.toString(), StandardCharsets.UTF_8);
+        }
         fields.put(HttpHeader.CONTENT_TYPE, MimeTypes.Type.TEXT_HTML_8859_1.asString());
         return BufferUtil.toBuffer("<h1>Bad Message " + status + "</h1><pre>reason: " + reason + "</pre>");
     }
@@ -599,6 +799,79 @@ public class ErrorHandler extends AbstractHandler
         return _showMessageInTitle;
     }
 
+    /**
+     * @return True if error responses are RFC 9457 problem details by default
+     * @see #setProblemDetails(boolean)
+     */
+    public boolean isProblemDetails()
+    {
+        return _problemDetails;
+    }
+
+    /**
+     * <p>Sets whether error responses are RFC 9457 problem details by default.</p>
+     * <p>When true, requests that accept {@code *&#47;*} or a JSON type, as well as
+     * requests without an {@code Accept} header, receive {@value #PROBLEM_JSON}
+     * error responses, while requests that prefer HTML or plain text still receive
+     * an error page.
+     * Requests that explicitly accept {@value #PROBLEM_JSON} receive problem details
+     * regardless of this setting.</p>
+     *
+     * @param problemDetails True if error responses are RFC 9457 problem details by default
+     */
+    public void setProblemDetails(boolean problemDetails)
+    {
+        _problemDetails = problemDetails;
+    }
+
+    /**
+     * @return True if the details of server errors are redacted from problem details
+     * @see #setRedactServerErrors(boolean)
+     */
+    public boolean isRedactServerErrors()
+    {
+        return _redactServerErrors;
+    }
+
+    /**
+     * <p>Sets whether the details of server errors are redacted from problem details.</p>
+     * <p>When true, the problem details of {@code 5xx} responses do not include the
+     * error message, the servlet name nor the stack trace, unless they are provided
+     * by a {@link ProblemMapper}, so that the internals of the server are not
+     * disclosed in production.</p>
+     *
+     * @param redactServerErrors True if the details of server errors are redacted from problem details
+     */
+    public void setRedactServerErrors(boolean redactServerErrors)
+    {
+        _redactServerErrors = redactServerErrors;
+    }
+
+    /**
+     * @return the mappers of exceptions to problem details
+     */
+    public List<ProblemMapper> getProblemMappers()
+    {
+        return _problemMappers;
+    }
+
+    /**
+     * @param mapper the mapper of exceptions to problem details to add
+     */
+    public void addProblemMapper(ProblemMapper mapper)
+    {
+        _problemMappers.add(Objects.requireNonNull(mapper));
+    }
+
+    /**
+     * @param mapper the mapper of exceptions to problem details to remove
+     * @return whether the mapper was removed
+     */
+    public boolean removeProblemMapper(ProblemMapper mapper)
+    {
+        return _problemMappers.remove(mapper);
+    }
+
     protected void write(Writer writer, String string)
         throws IOException
     {
@@ -613,6 +886,150 @@ public class ErrorHandler extends AbstractHandler
         String getErrorPage(HttpServletRequest request);
     }
 
+    /**
+     * <p>Maps the exceptions of error responses to RFC 9457 problem details.</p>
+     */
+    @FunctionalInterface
+    public interface ProblemMapper
+    {
+        /**
+         * <p>Maps an error to problem details.</p>
+         * <p>This method is called for the error exception and for each of its causes,
+         * and finally with a null exception, until a mapper returns non-null problem
+         * details.
+         * The status of the returned problem details is always overwritten with
+         * the error code, while its title, detail and instance are defaulted if null.</p>
+         *
+         * @param request The servlet request
+         * @param code the http error code
+         * @param failure the error exception or one of its causes, or null
+         * @return the problem details, or null if this mapper does not map the error
+         */
+        ProblemDetails map(HttpServletRequest request, int code, Throwable failure);
+    }
+
+    /**
+     * <p>The RFC 9457 problem details of an error response.</p>
+     */
+    public static class ProblemDetails
+    {
+        private final Map<String, Object> _extensions = new LinkedHashMap<>();
+        private String _type = "about:blank";
+        private String _title;
+        private int _status;
+        private String _detail;
+        private String _instance;
+
+        public ProblemDetails()
+        {
+        }
+
+        /**
+         * @param type the URI reference that identifies the problem type
+         * @param title the short summary of the problem type
+         */
+        public ProblemDetails(String type, String title)
+        {
+            setType(type);
+            setTitle(title);
+        }
+
+        /**
+         * @return the URI reference that identifies the problem type
+         */
+        public String getType()
+        {
+            return _type;
+        }
+
+        public void setType(String type)
+        {
+            _type = Objects.requireNonNull(type);
+        }
+
+        /**
+         * @return the short summary of the problem type
+         */
+        public String getTitle()
+        {
+            return _title;
+        }
+
+        public void setTitle(String title)
+        {
+            _title = title;
+        }
+
+        /**
+         * @return the HTTP status code
+         */
+        public int getStatus()
+        {
+            return _status;
+        }
+
+        public void setStatus(int status)
+        {
+            _status = status;
+        }
+
+        /**
+         * @return the explanation specific to this occurrence of the problem
+         */
+        public String getDetail()
+        {
+            return _detail;
+        }
+
+        public void setDetail(String detail)
+        {
+            _detail = detail;
+        }
+
+        /**
+         * @return the URI reference that identifies this occurrence of the problem
+         */
+        public String getInstance()
+        {
+            return _instance;
+        }
+
+        public void setInstance(String instance)
+        {
+            _instance = instance;
+        }
+
+        /**
+         * <p>Returns the extension members of the problem details.</p>
+         * <p>The values may be {@code null}, strings, numbers, booleans,
+         * collections, arrays or maps of such values; other values are
+         * written as strings.</p>
+         *
+         * @return the mutable map of the extension members
+         */
+        public Map<String, Object> getExtensions()
+        {
+            return _extensions;
+        }
+
+        /**
+         * @param name the name of the extension member
+         * @param value the value of the extension member
+         * @return this problem details
+         */
+        public ProblemDetails with(String name, Object value)
+        {
+            _extensions.put(name, value);
+            return this;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,%d,%s]", getClass().getSimpleName(), hashCode(), _type, _status, _title);
+        }
+    }
+
     public static ErrorHandler getErrorHandler(Server server, ContextHandler context)
     {
         ErrorHandler errorHandler = null;
@@ -0,0 +1,692 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.stream.Collectors;
+import javax.servlet.AsyncContext;
+import javax.servlet.DispatcherType;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.pathmap.PathSpec;
+import org.eclipse.jetty.io.ByteBufferAccumulator;
+import org.eclipse.jetty.io.EofException;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that injects faults in the handling of requests, to run chaos experiments
+ * against the clients of a service.</p>
+ * <p>Each {@link Rule} selects requests by path spec and header, samples them with a probability
+ * and, for the requests it selects, injects one {@link Fault}. Rules are evaluated in order and
+ * only the first rule that selects a request is applied.</p>
+ * <p>Rules can be configured at runtime via JMX, or from a string (see {@link #setRules(String)})
+ * made of comma separated rules of the form {@code fault[;name=value]*}, for example:</p>
+ * <pre>
+ * latency;path=/api/*;probability=0.1;latency=2000,error;header=X-Chaos:on;status=503
+ * </pre>
+ * <p>The rule parameters are:</p>
+ * <dl>
+ *   <dt>path</dt><dd>the {@link PathSpec} of the request path in context, by default all paths</dd>
+ *   <dt>header</dt><dd>a header name, or {@code name:value}, that the request must have</dd>
+ *   <dt>probability</dt><dd>the probability, between 0 and 1, that a matching request is selected, by default 1</dd>
+ *   <dt>latency</dt><dd>the delay in milliseconds of the {@code LATENCY} fault</dd>
+ *   <dt>blocking</dt><dd>whether the {@code LATENCY} fault blocks the handling thread, by default false</dd>
+ *   <dt>status</dt><dd>the status code of the {@code ERROR} fault</dd>
+ *   <dt>bytes</dt><dd>the number of content bytes sent by the {@code TRUNCATE} and {@code CORRUPT_CHUNKED} faults</dd>
+ * </dl>
+ * <p>The {@code LATENCY} fault delays the request asynchronously, like {@link AsyncDelayHandler}
+ * does, so that the delayed requests do not hold threads; the request is then dispatched again
+ * with its original dispatcher type. Only if the rule is {@code blocking}, the handling thread
+ * sleeps for the configured delay, which may starve the thread pool of a busy server.</p>
+ */
+@ManagedObject("Fault injection handler")
+public class FaultInjectionHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(FaultInjectionHandler.class);
+    private static final String DELAYED_ATTRIBUTE = FaultInjectionHandler.class.getName() + ".delayed";
+    private static final String[] ASYNC_ATTRIBUTES = {
+        AsyncContext.ASYNC_CONTEXT_PATH,
+        AsyncContext.ASYNC_PATH_INFO,
+        AsyncContext.ASYNC_QUERY_STRING,
+        AsyncContext.ASYNC_REQUEST_URI,
+        AsyncContext.ASYNC_SERVLET_PATH,
+        AsyncContext.ASYNC_MAPPING
+    };
+
+    private final List<Rule> _rules = new CopyOnWriteArrayList<>();
+    private final LongAdder _injected = new LongAdder();
+    private volatile boolean _enabled = true;
+
+    @ManagedAttribute("Whether faults are injected")
+    public boolean isEnabled()
+    {
+        return _enabled;
+    }
+
+    public void setEnabled(boolean enabled)
+    {
+        _enabled = enabled;
+    }
+
+    /**
+     * @return the rules, in the string form accepted by {@link #setRules(String)}
+     */
+    @ManagedAttribute("The fault injection rules")
+    public String getRules()
+    {
+        return _rules.stream().map(Rule::toString).collect(Collectors.joining(","));
+    }
+
+    /**
+     * <p>Replaces the rules with the given ones.</p>
+     *
+     * @param rules comma separated rules, or null or blank for no rules
+     */
+    public void setRules(String rules)
+    {
+        List<Rule> list = new CopyOnWriteArrayList<>();
+        if (StringUtil.isNotBlank(rules))
+        {
+            for (String rule : StringUtil.csvSplit(rules))
+            {
+                list.add(Rule.parse(rule));
+            }
+        }
+        _rules.clear();
+        _rules.addAll(list);
+    }
+
+    public void addRule(Rule rule)
+    {
+        _rules.add(rule);
+    }
+
+    /**
+     * @param rule the rule, in the form {@code fault[;name=value]*}
+     */
+    @ManagedOperation(value = "Adds a fault injection rule", impact = "ACTION")
+    public void addRule(@Name("rule") String rule)
+    {
+        addRule(Rule.parse(rule));
+    }
+
+    public boolean removeRule(Rule rule)
+    {
+        return _rules.remove(rule);
+    }
+
+    @ManagedOperation(value = "Removes all the fault injection rules", impact = "ACTION")
+    public void clearRules()
+    {
+        _rules.clear();
+    }
+
+    @ManagedAttribute("The number of injected faults")
+    public long getInjected()
+    {
+        return _injected.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void statsReset()
+    {
+        _injected.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        DispatcherType delayed = (DispatcherType)baseRequest.getAttribute(DELAYED_ATTRIBUTE);
+        if (delayed != null)
+        {
+            handleDelayed(delayed, target, baseRequest, request, response);
+            return;
+        }
+
+        Rule rule = isEnabled() ? select(baseRequest) : null;
+        if (rule == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        _injected.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Injecting {} in {}", rule, baseRequest);
+
+        switch (rule.getFault())
+        {
+            case LATENCY:
+                if (rule.isBlocking())
+                {
+                    sleep(rule.getLatency());
+                    super.handle(target, baseRequest, request, response);
+                }
+                else
+                {
+                    delay(baseRequest, rule.getLatency());
+                }
+                break;
+            case ABORT:
+                baseRequest.setHandled(true);
+                baseRequest.getHttpChannel().abort(new EofException("Fault injected"));
+                break;
+            case ERROR:
+                baseRequest.setHandled(true);
+                response.sendError(rule.getStatus());
+                break;
+            case TRUNCATE:
+            case CORRUPT_CHUNKED:
+                HttpOutput out = baseRequest.getResponse().getHttpOutput();
+                if (rule.getFault() == Fault.TRUNCATE)
+                    out.setInterceptor(new TruncateInterceptor(baseRequest, out.getInterceptor(), rule.getBytes()));
+                else
+                    out.setInterceptor(new CorruptChunkedInterceptor(baseRequest, out.getInterceptor(), rule.getBytes()));
+                super.handle(target, baseRequest, request, response);
+                break;
+            default:
+                throw new IllegalStateException();
+        }
+    }
+
+    /**
+     * @param request the request
+     * @return the first rule that selects the request, or null if no rule does
+     */
+    protected Rule select(Request request)
+    {
+        for (Rule rule : _rules)
+        {
+            if (rule.matches(request) && rule.sample())
+                return rule;
+        }
+        return null;
+    }
+
+    private void delay(Request baseRequest, long latency)
+    {
+        AsyncContext asyncContext = baseRequest.startAsync();
+        // The request is always dispatched again after the latency.
+        asyncContext.setTimeout(0);
+        baseRequest.setAttribute(DELAYED_ATTRIBUTE, baseRequest.getDispatcherType());
+        baseRequest.getHttpChannel().getScheduler().schedule(asyncContext::dispatch, latency, TimeUnit.MILLISECONDS);
+    }
+
+    private void handleDelayed(DispatcherType delayed, String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // Make the delayed request appear as the original one.
+        DispatcherType dispatcherType = baseRequest.getDispatcherType();
+        Object[] asyncAttributes = new Object[ASYNC_ATTRIBUTES.length];
+        baseRequest.removeAttribute(DELAYED_ATTRIBUTE);
+        baseRequest.setDispatcherType(delayed);
+        for (int i = 0; i < ASYNC_ATTRIBUTES.length; ++i)
+        {
+            asyncAttributes[i] = baseRequest.getAttribute(ASYNC_ATTRIBUTES[i]);
+            baseRequest.setAttribute(ASYNC_ATTRIBUTES[i], null);
+        }
+        try
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            baseRequest.setDispatcherType(dispatcherType);
+            for (int i = 0; i < ASYNC_ATTRIBUTES.length; ++i)
+            {
+                baseRequest.setAttribute(ASYNC_ATTRIBUTES[i], asyncAttributes[i]);
+            }
+        }
+    }
+
+    private static void sleep(long millis) throws IOException
+    {
+        try
+        {
+            TimeUnit.MILLISECONDS.sleep(millis);
+        }
+        catch (InterruptedException x)
+        {
+            Thread.currentThread().interrupt();
+            throw new IOException(x);
+        }
+    }
+
+    /**
+     * <p>The faults that can be injected.</p>
+     */
+    public enum Fault
+    {
+        /**
+         * <p>Delays the handling of the request.</p>
+         */
+        LATENCY,
+        /**
+         * <p>Aborts the connection, or the stream for HTTP/2 and HTTP/3, without a response.</p>
+         */
+        ABORT,
+        /**
+         * <p>Sends an error response instead of handling the request.</p>
+         */
+        ERROR,
+        /**
+         * <p>Aborts the response after a number of content bytes have been sent.</p>
+         */
+        TRUNCATE,
+        /**
+         * <p>Sends the response with an invalid chunk size after a number of content bytes,
+         * then closes the connection; applies only to HTTP/1.1 requests.</p>
+         */
+        CORRUPT_CHUNKED
+    }
+
+    /**
+     * <p>A rule selecting the requests in which a {@link Fault} is injected.</p>
+     */
+    public static class Rule
+    {
+        private final Fault _fault;
+        private PathSpec _pathSpec;
+        private String _headerName;
+        private String _headerValue;
+        private double _probability = 1.0D;
+        private long _latency = 1000;
+        private boolean _blocking;
+        private int _status = HttpStatus.SERVICE_UNAVAILABLE_503;
+        private long _bytes;
+
+        public Rule(Fault fault)
+        {
+            _fault = fault;
+        }
+
+        /**
+         * <p>Parses a rule in the form {@code fault[;name=value]*}.</p>
+         *
+         * @param rule the rule string
+         * @return the rule
+         * @throws IllegalArgumentException if the rule string is invalid
+         */
+        public static Rule parse(String rule)
+        {
+            String[] parts = rule.trim().split(";");
+            Rule result = new Rule(Fault.valueOf(StringUtil.asciiToUpperCase(parts[0].trim()).replace('-', '_')));
+            for (int i = 1; i < parts.length; ++i)
+            {
+                String part = parts[i].trim();
+                int equals = part.indexOf('=');
+                if (equals < 0)
+                    throw new IllegalArgumentException("Invalid rule parameter " + part + " in " + rule);
+                String name = part.substring(0, equals).trim();
+                String value = part.substring(equals + 1).trim();
+                switch (name)
+                {
+                    case "path":
+                        result.setPath(value);
+                        break;
+                    case "header":
+                        int colon = value.indexOf(':');
+                        if (colon < 0)
+                            result.setHeader(value, null);
+                        else
+                            result.setHeader(value.substring(0, colon).trim(), value.substring(colon + 1).trim());
+                        break;
+                    case "probability":
+                        result.setProbability(Double.parseDouble(value));
+                        break;
+                    case "latency":
+                        result.setLatency(Long.parseLong(value));
+                        break;
+                    case "blocking":
+                        result.setBlocking(Boolean.parseBoolean(value));
+                        break;
+                    case "status":
+                        result.setStatus(Integer.parseInt(value));
+                        break;
+                    case "bytes":
+                        result.setBytes(Long.parseLong(value));
+                        break;
+                    default:
+                        throw new IllegalArgumentException("Unknown rule parameter " + name + " in " + rule);
+                }
+            }
+            return result;
+        }
+
+        public Fault getFault()
+        {
+            return _fault;
+        }
+
+        /**
+         * @return the path spec of the selected requests, or null for all paths
+         */
+        public String getPath()
+        {
+            return _pathSpec == null ? null : _pathSpec.getDeclaration();
+        }
+
+        /**
+         * @param pathSpec the path spec of the selected requests, or null for all paths
+         */
+        public void setPath(String pathSpec)
+        {
+            _pathSpec = pathSpec == null ? null : PathSpec.from(pathSpec);
+        }
+
+        public String getHeaderName()
+        {
+            return _headerName;
+        }
+
+        public String getHeaderValue()
+        {
+            return _headerValue;
+        }
+
+        /**
+         * @param name the name of the header the selected requests must have, or null for any request
+         * @param value the value the header must have, or null for any value
+         */
+        public void setHeader(String name, String value)
+        {
+            _headerName = name;
+            _headerValue = value;
+        }
+
+        public double getProbability()
+        {
+            return _probability;
+        }
+
+        /**
+         * @param probability the probability, between 0 and 1, that a matching request is selected
+         */
+        public void setProbability(double probability)
+        {
+            if (probability < 0 || probability > 1)
+                throw new IllegalArgumentException("Invalid probability " + probability);
+            _probability = probability;
+        }
+
+        /**
+         * @return the delay in milliseconds of the {@link Fault#LATENCY} fault
+         */
+        public long getLatency()
+        {
+            return _latency;
+        }
+
+        public void setLatency(long latency)
+        {
+            _latency = latency;
+        }
+
+        /**
+         * @return whether the {@link Fault#LATENCY} fault blocks the handling thread,
+         * rather than delaying the request asynchronously
+         */
+        public boolean isBlocking()
+        {
+            return _blocking;
+        }
+
+        public void setBlocking(boolean blocking)
+        {
+            _blocking = blocking;
+        }
+
+        /**
+         * @return the status code of the {@link Fault#ERROR} fault
+         */
+        public int getStatus()
+        {
+            return _status;
+        }
+
+        public void setStatus(int status)
+        {
+            if (!HttpStatus.isClientError(status) && !HttpStatus.isServerError(status))
+                throw new IllegalArgumentException("Invalid error status " + status);
+            _status = status;
+        }
+
+        /**
+         * @return the number of content bytes sent by the {@link Fault#TRUNCATE}
+         * and {@link Fault#CORRUPT_CHUNKED} faults
+         */
+        public long getBytes()
+        {
+            return _bytes;
+        }
+
+        public void setBytes(long bytes)
+        {
+            _bytes = bytes;
+        }
+
+        /**
+         * @param request the request
+         * @return whether the request matches the path spec and the header of this rule
+         */
+        public boolean matches(Request request)
+        {
+            if (_fault == Fault.CORRUPT_CHUNKED && request.getHttpVersion() != HttpVersion.HTTP_1_1)
+                return false;
+            if (_pathSpec != null && !_pathSpec.matches(request.getPathInContext()))
+                return false;
+            if (_headerName != null)
+            {
+                String value = request.getHeader(_headerName);
+                if (value == null)
+                    return false;
+                return _headerValue == null || _headerValue.equalsIgnoreCase(value);
+            }
+            return true;
+        }
+
+        /**
+         * @return whether a matching request is selected, according to the probability of this rule
+         */
+        protected boolean sample()
+        {
+            return _probability >= 1.0D || ThreadLocalRandom.current().nextDouble() < _probability;
+        }
+
+        @Override
+        public String toString()
+        {
+            StringBuilder builder = new StringBuilder(_fault.name());
+            if (_pathSpec != null)
+                builder.append(";path=").append(_pathSpec.getDeclaration());
+            if (_headerName != null)
+            {
+                builder.append(";header=").append(_headerName);
+                if (_headerValue != null)
+                    builder.append(':').append(_headerValue);
+            }
+            if (_probability < 1.0D)
+                builder.append(";probability=").append(_probability);
+            switch (_fault)
+            {
+                case LATENCY:
+                    builder.append(";latency=").append(_latency);
+                    if (_blocking)
+                        builder.append(";blocking=true");
+                    break;
+                case ERROR:
+                    builder.append(";status=").append(_status);
+                    break;
+                case TRUNCATE:
+                case CORRUPT_CHUNKED:
+                    builder.append(";bytes=").append(_bytes);
+                    break;
+                default:
+                    break;
+            }
+            return builder.toString();
+        }
+    }
+
+    private static class TruncateInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request _request;
+        private final HttpOutput.Interceptor _next;
+        private long _remaining;
+
+        private TruncateInterceptor(Request request, HttpOutput.Interceptor next, long bytes)
+        {
+            _request = request;
+            _next = next;
+            _remaining = bytes;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            int length = BufferUtil.length(content);
+            if (length <= _remaining)
+            {
+                _remaining -= length;
+                if (!last)
+                {
+                    _next.write(content, false, callback);
+                    return;
+                }
+                // The complete response would be sent, so it is truncated before the last write.
+                _next.write(content, false, Callback.from(() -> abort(callback), callback::failed));
+                return;
+            }
+
+            ByteBuffer head = content.slice();
+            head.limit((int)_remaining);
+            content.position(content.limit());
+            _remaining = 0;
+            _next.write(head, false, Callback.from(() -> abort(callback), callback::failed));
+        }
+
+        private void abort(Callback callback)
+        {
+            EofException failure = new EofException("Fault injected");
+            _request.getHttpChannel().abort(failure);
+            callback.failed(failure);
+        }
+    }
+
+    private static class CorruptChunkedInterceptor implements HttpOutput.Interceptor
+    {
+        private final ByteBufferAccumulator _content = new ByteBufferAccumulator();
+        private final Request _request;
+        private final HttpOutput.Interceptor _next;
+        private final long _bytes;
+
+        private CorruptChunkedInterceptor(Request request, HttpOutput.Interceptor next, long bytes)
+        {
+            _request = request;
+            _next = next;
+            _bytes = bytes;
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        @Override
+        public void resetBuffer()
+        {
+            _content.close();
+            HttpOutput.Interceptor.super.resetBuffer();
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            Response response = _request.getResponse();
+            if (response.isCommitted())
+            {
+                // Too late to take over the response.
+                _next.write(content, last, callback);
+                return;
+            }
+
+            if (BufferUtil.hasContent(content))
+                _content.copyBuffer(content);
+            if (!last)
+            {
+                callback.succeeded();
+                return;
+            }
+
+            // Bypass the generator, writing the response directly to the endpoint.
+            ByteBuffer body = BufferUtil.toBuffer(_content.toByteArray());
+            _content.close();
+            int length = (int)Math.min(_bytes, body.remaining());
+            StringBuilder head = new StringBuilder();
+            head.append(HttpVersion.HTTP_1_1).append(' ').append(response.getStatus()).append(' ').append(HttpStatus.getMessage(response.getStatus())).append("\r\n");
+            for (HttpField field : response.getHttpFields())
+            {
+                if (field.getHeader() != HttpHeader.CONTENT_LENGTH && field.getHeader() != HttpHeader.TRANSFER_ENCODING)
+                    head.append(field.getName()).append(": ").append(field.getValue()).append("\r\n");
+            }
+            head.append("Transfer-Encoding: chunked\r\n\r\n");
+            if (length > 0)
+                head.append(Integer.toHexString(length)).append("\r\n");
+
+            ByteBuffer chunk = body.slice();
+            chunk.limit(length);
+            // Not a valid hexadecimal chunk size.
+            ByteBuffer corrupt = BufferUtil.toBuffer((length > 0 ? "\r\n" : "") + "ZZ\r\n", StandardCharsets.US_ASCII);
+            _request.getHttpChannel().getEndPoint().write(Callback.from(() -> abort(callback), x -> abort(callback)),
+                BufferUtil.toBuffer(head.toString(), StandardCharsets.ISO_8859_1), chunk, corrupt);
+        }
+
+        private void abort(Callback callback)
+        {
+            EofException failure = new EofException("Fault injected");
+            _request.getHttpChannel().abort(failure);
+            callback.failed(failure);
+        }
+    }
+}
@@ -23,6 +23,7 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.server.Handler;
 import org.eclipse.jetty.server.HandlerContainer;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.util.ArrayUtil;
 import org.eclipse.jetty.util.MultiException;
@@ -138,7 +139,7 @@ public class HandlerCollection extends AbstractHandlerContainer
             {
                 try
                 {
-                    handler.handle(target, baseRequest, request, response);
+                    HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
                 }
                 catch (IOException | RuntimeException e)
                 {
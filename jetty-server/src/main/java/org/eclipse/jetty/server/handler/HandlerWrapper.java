@@ -22,6 +22,7 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.server.Handler;
 import org.eclipse.jetty.server.HandlerContainer;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
@@ -119,7 +120,7 @@ public class HandlerWrapper extends AbstractHandlerContainer
     {
         Handler handler = _handler;
         if (handler != null)
-            handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
     }
 
     @Override
@@ -0,0 +1,600 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.security.SecureRandom;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.CopyOnWriteArrayList;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.http.pathmap.PathSpecSet;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.IncludeExcludeSet;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that post-processes the response headers just before the response is committed.</p>
+ * <p>Headers are modified by {@link Rule}s that {@link Mode#ADD add}, {@link Mode#SET set},
+ * {@link Mode#SET_IF_ABSENT set if absent} or {@link Mode#REMOVE remove} a header, optionally
+ * only when the request path, the response status, the response content type or the request
+ * scheme match the rule conditions.
+ * Rules are applied in the order they have been added, after the security presets.</p>
+ * <p>Security presets are available for the common security headers, see for example
+ * {@link #setStrictTransportSecurity(String)} or {@link #setContentSecurityPolicy(String)};
+ * presets only set the header if the application did not set it already, and
+ * {@link #setSecurityDefaults()} configures the presets that are safe for most web applications.</p>
+ * <p>A header value may contain the {@value #NONCE_PLACEHOLDER} placeholder, typically in a
+ * {@code Content-Security-Policy} such as {@code script-src 'nonce-{nonce}'}: in this case a
+ * random nonce is generated for each request, is exported as the {@value #CSP_NONCE_ATTRIBUTE}
+ * request attribute so that the application can use it in the generated content, and replaces
+ * the placeholder in the header value.</p>
+ * <p>Headers that are generated by the connection (such as {@code Server} or {@code Date})
+ * are not part of the response headers and therefore cannot be modified by this handler.</p>
+ */
+@ManagedObject("Response headers handler")
+public class HeadersHandler extends HandlerWrapper
+{
+    public static final String CSP_NONCE_ATTRIBUTE = "org.eclipse.jetty.server.handler.HeadersHandler.cspNonce";
+    public static final String NONCE_PLACEHOLDER = "{nonce}";
+    private static final Logger LOG = LoggerFactory.getLogger(HeadersHandler.class);
+
+    private final SecureRandom _random = new SecureRandom();
+    private final Map<String, Rule> _presets = new ConcurrentHashMap<>();
+    private final List<Rule> _rules = new CopyOnWriteArrayList<>();
+    private int _nonceBytes = 16;
+
+    /**
+     * <p>Adds a rule that adds a header value, even if the header is already present.</p>
+     *
+     * @param name the header name
+     * @param value the header value
+     * @return the rule, whose conditions can be further configured
+     */
+    public Rule add(String name, String value)
+    {
+        return addRule(new Rule(Mode.ADD, name, Objects.requireNonNull(value)));
+    }
+
+    /**
+     * <p>Adds a rule that sets a header value, replacing any existing value.</p>
+     *
+     * @param name the header name
+     * @param value the header value
+     * @return the rule, whose conditions can be further configured
+     */
+    public Rule set(String name, String value)
+    {
+        return addRule(new Rule(Mode.SET, name, Objects.requireNonNull(value)));
+    }
+
+    /**
+     * <p>Adds a rule that sets a header value only if the header is not already present.</p>
+     *
+     * @param name the header name
+     * @param value the header value
+     * @return the rule, whose conditions can be further configured
+     */
+    public Rule setIfAbsent(String name, String value)
+    {
+        return addRule(new Rule(Mode.SET_IF_ABSENT, name, Objects.requireNonNull(value)));
+    }
+
+    /**
+     * <p>Adds a rule that removes a header.</p>
+     *
+     * @param name the header name
+     * @return the rule, whose conditions can be further configured
+     */
+    public Rule remove(String name)
+    {
+        return addRule(new Rule(Mode.REMOVE, name, null));
+    }
+
+    private Rule addRule(Rule rule)
+    {
+        _rules.add(rule);
+        return rule;
+    }
+
+    /**
+     * @param rule the rule to remove
+     * @return whether the rule was removed
+     */
+    public boolean removeRule(Rule rule)
+    {
+        return _rules.remove(rule);
+    }
+
+    /**
+     * @return the rules, not including the security presets
+     */
+    public List<Rule> getRules()
+    {
+        return new ArrayList<>(_rules);
+    }
+
+    @ManagedOperation(value = "Removes all rules and presets", impact = "ACTION")
+    public void clear()
+    {
+        _rules.clear();
+        _presets.clear();
+    }
+
+    @ManagedAttribute("The number of random bytes of the CSP nonce")
+    public int getNonceBytes()
+    {
+        return _nonceBytes;
+    }
+
+    public void setNonceBytes(int nonceBytes)
+    {
+        if (nonceBytes < 16)
+            throw new IllegalArgumentException("Invalid nonce bytes " + nonceBytes);
+        _nonceBytes = nonceBytes;
+    }
+
+    /**
+     * <p>Configures the security presets that are safe for most web applications:</p>
+     * <ul>
+     * <li>{@code Strict-Transport-Security: max-age=31536000; includeSubDomains}</li>
+     * <li>{@code X-Content-Type-Options: nosniff}</li>
+     * <li>{@code Referrer-Policy: strict-origin-when-cross-origin}</li>
+     * <li>{@code Cross-Origin-Opener-Policy: same-origin}</li>
+     * </ul>
+     * <p>{@code Content-Security-Policy} and {@code Cross-Origin-Embedder-Policy}
+     * depend on the application and must be configured explicitly.</p>
+     */
+    @ManagedOperation(value = "Configures the default security presets", impact = "ACTION")
+    public void setSecurityDefaults()
+    {
+        setStrictTransportSecurity("max-age=31536000; includeSubDomains");
+        setContentTypeOptions("nosniff");
+        setReferrerPolicy("strict-origin-when-cross-origin");
+        setCrossOriginOpenerPolicy("same-origin");
+    }
+
+    @ManagedAttribute("The Strict-Transport-Security preset")
+    public String getStrictTransportSecurity()
+    {
+        return getPreset(HttpHeader.STRICT_TRANSPORT_SECURITY.asString());
+    }
+
+    /**
+     * <p>Sets the {@code Strict-Transport-Security} preset, only sent for secure requests.</p>
+     *
+     * @param value the header value, such as {@code max-age=31536000; includeSubDomains},
+     * or {@code null} to disable this preset
+     */
+    public void setStrictTransportSecurity(String value)
+    {
+        Rule rule = setPreset(HttpHeader.STRICT_TRANSPORT_SECURITY.asString(), value);
+        if (rule != null)
+            rule.secure(true);
+    }
+
+    @ManagedAttribute("The Content-Security-Policy preset")
+    public String getContentSecurityPolicy()
+    {
+        return getPreset("Content-Security-Policy");
+    }
+
+    /**
+     * <p>Sets the {@code Content-Security-Policy} preset.</p>
+     * <p>The value may contain the {@value #NONCE_PLACEHOLDER} placeholder,
+     * for example {@code script-src 'nonce-{nonce}'}.</p>
+     *
+     * @param value the header value, or {@code null} to disable this preset
+     */
+    public void setContentSecurityPolicy(String value)
+    {
+        setPreset("Content-Security-Policy", value);
+    }
+
+    @ManagedAttribute("The X-Content-Type-Options preset")
+    public String getContentTypeOptions()
+    {
+        return getPreset("X-Content-Type-Options");
+    }
+
+    /**
+     * @param value the {@code X-Content-Type-Options} value, normally {@code nosniff},
+     * or {@code null} to disable this preset
+     */
+    public void setContentTypeOptions(String value)
+    {
+        setPreset("X-Content-Type-Options", value);
+    }
+
+    @ManagedAttribute("The Referrer-Policy preset")
+    public String getReferrerPolicy()
+    {
+        return getPreset("Referrer-Policy");
+    }
+
+    /**
+     * @param value the {@code Referrer-Policy} value, or {@code null} to disable this preset
+     */
+    public void setReferrerPolicy(String value)
+    {
+        setPreset("Referrer-Policy", value);
+    }
+
+    @ManagedAttribute("The Cross-Origin-Opener-Policy preset")
+    public String getCrossOriginOpenerPolicy()
+    {
+        return getPreset("Cross-Origin-Opener-Policy");
+    }
+
+    /**
+     * @param value the {@code Cross-Origin-Opener-Policy} value, or {@code null} to disable this preset
+     */
+    public void setCrossOriginOpenerPolicy(String value)
+    {
+        setPreset("Cross-Origin-Opener-Policy", value);
+    }
+
+    @ManagedAttribute("The Cross-Origin-Embedder-Policy preset")
+    public String getCrossOriginEmbedderPolicy()
+    {
+        return getPreset("Cross-Origin-Embedder-Policy");
+    }
+
+    /**
+     * @param value the {@code Cross-Origin-Embedder-Policy} value, such as {@code require-corp},
+     * or {@code null} to disable this preset
+     */
+    public void setCrossOriginEmbedderPolicy(String value)
+    {
+        setPreset("Cross-Origin-Embedder-Policy", value);
+    }
+
+    private String getPreset(String name)
+    {
+        Rule rule = _presets.get(name);
+        return rule == null ? null : rule.getValue();
+    }
+
+    private Rule setPreset(String name, String value)
+    {
+        if (StringUtil.isBlank(value))
+        {
+            _presets.remove(name);
+            return null;
+        }
+        Rule rule = new Rule(Mode.SET_IF_ABSENT, name, value.trim());
+        _presets.put(name, rule);
+        return rule;
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        if (_presets.isEmpty() && _rules.isEmpty() || isIntercepted(out))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        List<Rule> rules = new ArrayList<>(_presets.values());
+        rules.addAll(_rules);
+
+        String nonce = null;
+        for (Rule rule : rules)
+        {
+            if (rule.hasNonce())
+            {
+                nonce = newNonce();
+                baseRequest.setAttribute(CSP_NONCE_ATTRIBUTE, nonce);
+                break;
+            }
+        }
+
+        out.setInterceptor(new HeadersInterceptor(baseRequest, baseRequest.getPathInContext(), rules, nonce, out.getInterceptor()));
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private boolean isIntercepted(HttpOutput out)
+    {
+        HttpOutput.Interceptor interceptor = out.getInterceptor();
+        while (interceptor != null)
+        {
+            if (interceptor instanceof HeadersInterceptor && ((HeadersInterceptor)interceptor).getHandler() == this)
+                return true;
+            interceptor = interceptor.getNextInterceptor();
+        }
+        return false;
+    }
+
+    protected String newNonce()
+    {
+        byte[] bytes = new byte[getNonceBytes()];
+        _random.nextBytes(bytes);
+        return Base64.getEncoder().encodeToString(bytes);
+    }
+
+    /**
+     * The header modification modes.
+     */
+    public enum Mode
+    {
+        /**
+         * Adds the header value, even if the header is already present.
+         */
+        ADD,
+        /**
+         * Sets the header value, replacing any existing value.
+         */
+        SET,
+        /**
+         * Sets the header value only if the header is not already present.
+         */
+        SET_IF_ABSENT,
+        /**
+         * Removes the header.
+         */
+        REMOVE
+    }
+
+    /**
+     * <p>A header modification rule, with optional conditions.</p>
+     * <p>When no condition is configured, the rule applies to every response.</p>
+     */
+    public static class Rule
+    {
+        private final IncludeExcludeSet<String, String> _paths = new IncludeExcludeSet<>(PathSpecSet.class);
+        private final IncludeExclude<Integer> _statuses = new IncludeExclude<>();
+        private final IncludeExclude<String> _mimeTypes = new IncludeExclude<>();
+        private final Mode _mode;
+        private final String _name;
+        private final String _value;
+        private Boolean _secure;
+
+        private Rule(Mode mode, String name, String value)
+        {
+            if (StringUtil.isBlank(name))
+                throw new IllegalArgumentException("Invalid header name");
+            _mode = mode;
+            _name = name;
+            _value = value;
+        }
+
+        public Mode getMode()
+        {
+            return _mode;
+        }
+
+        public String getName()
+        {
+            return _name;
+        }
+
+        public String getValue()
+        {
+            return _value;
+        }
+
+        private boolean hasNonce()
+        {
+            return _value != null && _value.contains(NONCE_PLACEHOLDER);
+        }
+
+        /**
+         * @param pathSpecs the path specs, relative to the context, the request must match
+         * @return this rule
+         */
+        public Rule includePaths(String... pathSpecs)
+        {
+            _paths.include(pathSpecs);
+            return this;
+        }
+
+        /**
+         * @param pathSpecs the path specs, relative to the context, the request must not match
+         * @return this rule
+         */
+        public Rule excludePaths(String... pathSpecs)
+        {
+            _paths.exclude(pathSpecs);
+            return this;
+        }
+
+        /**
+         * @param statuses the response status codes the rule applies to
+         * @return this rule
+         */
+        public Rule includeStatuses(int... statuses)
+        {
+            for (int status : statuses)
+            {
+                _statuses.include(status);
+            }
+            return this;
+        }
+
+        /**
+         * @param statuses the response status codes the rule does not apply to
+         * @return this rule
+         */
+        public Rule excludeStatuses(int... statuses)
+        {
+            for (int status : statuses)
+            {
+                _statuses.exclude(status);
+            }
+            return this;
+        }
+
+        /**
+         * @param mimeTypes the response mime types, without parameters, the rule applies to
+         * @return this rule
+         */
+        public Rule includeMimeTypes(String... mimeTypes)
+        {
+            for (String mimeType : mimeTypes)
+            {
+                _mimeTypes.include(StringUtil.asciiToLowerCase(mimeType));
+            }
+            return this;
+        }
+
+        /**
+         * @param mimeTypes the response mime types, without parameters, the rule does not apply to
+         * @return this rule
+         */
+        public Rule excludeMimeTypes(String... mimeTypes)
+        {
+            for (String mimeType : mimeTypes)
+            {
+                _mimeTypes.exclude(StringUtil.asciiToLowerCase(mimeType));
+            }
+            return this;
+        }
+
+        /**
+         * @param secure whether the rule only applies to secure requests ({@code true})
+         * or only to non secure requests ({@code false})
+         * @return this rule
+         */
+        public Rule secure(boolean secure)
+        {
+            _secure = secure;
+            return this;
+        }
+
+        protected boolean matches(Request request, String path, Response response)
+        {
+            if (_secure != null && _secure != request.isSecure())
+                return false;
+            if (!_paths.isEmpty() && (path == null || !_paths.test(path)))
+                return false;
+            if (!_statuses.isEmpty() && !_statuses.test(response.getStatus()))
+                return false;
+            if (!_mimeTypes.isEmpty())
+            {
+                String contentType = response.getContentType();
+                String mimeType = contentType == null ? null : StringUtil.asciiToLowerCase(MimeTypes.getContentTypeWithoutCharset(contentType));
+                if (mimeType == null || !_mimeTypes.test(mimeType))
+                    return false;
+            }
+            return true;
+        }
+
+        protected void apply(HttpFields.Mutable headers, String nonce)
+        {
+            String value = _value;
+            if (value != null && nonce != null)
+                value = value.replace(NONCE_PLACEHOLDER, nonce);
+            switch (_mode)
+            {
+                case ADD:
+                    headers.add(_name, value);
+                    break;
+                case SET:
+                    headers.put(_name, value);
+                    break;
+                case SET_IF_ABSENT:
+                    if (!headers.contains(_name))
+                        headers.put(_name, value);
+                    break;
+                case REMOVE:
+                    headers.remove(_name);
+                    break;
+                default:
+                    throw new IllegalStateException();
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s %s: %s}", getClass().getSimpleName(), hashCode(), _mode, _name, _value);
+        }
+    }
+
+    private class HeadersInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request _request;
+        private final String _path;
+        private final List<Rule> _rules;
+        private final String _nonce;
+        private final HttpOutput.Interceptor _next;
+        private boolean _applied;
+
+        private HeadersInterceptor(Request request, String path, List<Rule> rules, String nonce, HttpOutput.Interceptor next)
+        {
+            _request = request;
+            _path = path;
+            _rules = rules;
+            _nonce = nonce;
+            _next = next;
+        }
+
+        private HeadersHandler getHandler()
+        {
+            return HeadersHandler.this;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            if (!_applied)
+            {
+                _applied = true;
+                Response response = _request.getResponse();
+                if (!response.isCommitted())
+                {
+                    HttpFields.Mutable headers = response.getHttpFields();
+                    for (Rule rule : _rules)
+                    {
+                        if (rule.matches(_request, _path, response))
+                        {
+                            if (LOG.isDebugEnabled())
+                                LOG.debug("Applying {} to {}", rule, _request);
+                            rule.apply(headers, _nonce);
+                        }
+                    }
+                }
+            }
+            _next.write(content, last, callback);
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+    }
+}
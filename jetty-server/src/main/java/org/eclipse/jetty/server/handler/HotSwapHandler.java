@@ -20,6 +20,7 @@ import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Server;
 
@@ -104,7 +105,7 @@ public class HotSwapHandler extends AbstractHandlerContainer
         Handler handler = _handler;
         if (handler != null && isStarted() && handler.isStarted())
         {
-            handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
         }
     }
 
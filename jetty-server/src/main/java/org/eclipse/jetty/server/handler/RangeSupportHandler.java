@@ -0,0 +1,212 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.util.Enumeration;
+import java.util.List;
+import javax.servlet.ServletException;
+import javax.servlet.ServletRequest;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.DateParser;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.server.InclusiveByteRange;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.MultiPartOutputStream;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that supports HTTP range requests for dynamically generated content.</p>
+ * <p>While {@link org.eclipse.jetty.server.ResourceService} handles ranges only for static
+ * resources, this handler sends the {@link SeekableContentSource} produced by the wrapped
+ * handler, that is {@link #setContentSource(ServletRequest, SeekableContentSource) set} as
+ * a request attribute before the wrapped handler returns, rather than written to the response.</p>
+ * <p>For {@code GET} requests with a {@code Range} header, a single byte range is sent in a
+ * {@code 206} response, multiple byte ranges are sent in a {@code multipart/byteranges}
+ * {@code 206} response, and unsatisfiable ranges produce a {@code 416} response.
+ * The {@code If-Range} header is validated against the entity tag or the last modified
+ * time of the content source, and the whole content is sent if the validation fails.</p>
+ * <p>For example, a media server generating content from a blob store would use:</p>
+ * <pre>{@code
+ * RangeSupportHandler rangeSupport = new RangeSupportHandler();
+ * rangeSupport.setHandler(new AbstractHandler()
+ * {
+ *     public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+ *     {
+ *         Blob blob = blobStore.get(target);
+ *         RangeSupportHandler.setContentSource(request, new BlobContentSource(blob));
+ *         baseRequest.setHandled(true);
+ *     }
+ * });
+ * }</pre>
+ */
+@ManagedObject("Range support handler")
+public class RangeSupportHandler extends HandlerWrapper
+{
+    public static final String CONTENT_SOURCE_ATTRIBUTE = RangeSupportHandler.class.getName() + ".contentSource";
+    private static final Logger LOG = LoggerFactory.getLogger(RangeSupportHandler.class);
+
+    /**
+     * <p>Sets the content to send for the given request.</p>
+     *
+     * @param request the request
+     * @param source the content to send
+     */
+    public static void setContentSource(ServletRequest request, SeekableContentSource source)
+    {
+        request.setAttribute(CONTENT_SOURCE_ATTRIBUTE, source);
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        super.handle(target, baseRequest, request, response);
+
+        Object source = request.getAttribute(CONTENT_SOURCE_ATTRIBUTE);
+        if (!(source instanceof SeekableContentSource))
+            return;
+        request.removeAttribute(CONTENT_SOURCE_ATTRIBUTE);
+        baseRequest.setHandled(true);
+
+        if (response.isCommitted())
+        {
+            LOG.warn("Response already committed, ignoring content source for {}", request.getRequestURI());
+            return;
+        }
+        sendContent(request, response, (SeekableContentSource)source);
+    }
+
+    /**
+     * <p>Sends the given content, or the byte ranges of it requested by the {@code Range} header.</p>
+     *
+     * @param request the request
+     * @param response the response
+     * @param source the content to send
+     * @throws IOException if the content cannot be sent
+     */
+    protected void sendContent(HttpServletRequest request, HttpServletResponse response, SeekableContentSource source) throws IOException
+    {
+        long length = source.getLength();
+        String contentType = source.getContentType();
+        if (contentType != null)
+            response.setContentType(contentType);
+        String etag = source.getETag();
+        if (etag != null)
+            response.setHeader(HttpHeader.ETAG.asString(), etag);
+        long lastModified = source.getLastModified();
+        if (lastModified >= 0)
+            response.setDateHeader(HttpHeader.LAST_MODIFIED.asString(), lastModified);
+        response.setHeader(HttpHeader.ACCEPT_RANGES.asString(), "bytes");
+
+        boolean head = HttpMethod.HEAD.is(request.getMethod());
+        Enumeration<String> reqRanges = request.getHeaders(HttpHeader.RANGE.asString());
+        // Range requests are only defined for GET.
+        if (!HttpMethod.GET.is(request.getMethod()) || reqRanges == null || !reqRanges.hasMoreElements() || !passIfRange(request, source))
+        {
+            response.setContentLengthLong(length);
+            if (!head)
+                source.writeTo(response.getOutputStream(), 0, length);
+            return;
+        }
+
+        List<InclusiveByteRange> ranges = InclusiveByteRange.satisfiableRanges(reqRanges, length);
+        if (ranges == null || ranges.isEmpty())
+        {
+            response.setHeader(HttpHeader.CONTENT_RANGE.asString(), InclusiveByteRange.to416HeaderRangeString(length));
+            response.setContentLength(0);
+            response.setStatus(HttpServletResponse.SC_REQUESTED_RANGE_NOT_SATISFIABLE);
+            return;
+        }
+
+        OutputStream out = response.getOutputStream();
+        response.setStatus(HttpServletResponse.SC_PARTIAL_CONTENT);
+        if (ranges.size() == 1)
+        {
+            InclusiveByteRange range = ranges.get(0);
+            response.setHeader(HttpHeader.CONTENT_RANGE.asString(), range.toHeaderRangeString(length));
+            response.setContentLengthLong(range.getSize());
+            source.writeTo(out, range.getFirst(), range.getSize());
+            return;
+        }
+
+        MultiPartOutputStream multi = new MultiPartOutputStream(out);
+        response.setContentType("multipart/byteranges; boundary=" + multi.getBoundary());
+
+        // Calculate the content length of the multipart content.
+        long multiLength = 0;
+        String[] headers = new String[ranges.size()];
+        final int CRLF = "\r\n".length();
+        final int DASHDASH = "--".length();
+        final int BOUNDARY = multi.getBoundary().length();
+        final int FIELD_SEP = ": ".length();
+        for (int i = 0; i < ranges.size(); i++)
+        {
+            InclusiveByteRange range = ranges.get(i);
+            headers[i] = range.toHeaderRangeString(length);
+            if (i > 0)
+                multiLength += CRLF;
+            multiLength += DASHDASH + BOUNDARY + CRLF;
+            if (contentType != null)
+                multiLength += HttpHeader.CONTENT_TYPE.asString().length() + FIELD_SEP + contentType.length() + CRLF;
+            multiLength += HttpHeader.CONTENT_RANGE.asString().length() + FIELD_SEP + headers[i].length() + CRLF;
+            multiLength += CRLF;
+            multiLength += range.getSize();
+        }
+        multiLength += CRLF + DASHDASH + BOUNDARY + DASHDASH + CRLF;
+        response.setContentLengthLong(multiLength);
+
+        for (int i = 0; i < ranges.size(); i++)
+        {
+            InclusiveByteRange range = ranges.get(i);
+            multi.startPart(contentType, new String[]{HttpHeader.CONTENT_RANGE + ": " + headers[i]});
+            source.writeTo(multi, range.getFirst(), range.getSize());
+        }
+        multi.close();
+    }
+
+    /**
+     * <p>Validates the {@code If-Range} request header, if any, against the given content.</p>
+     * <p>An entity tag matches only with a strong comparison, and a date matches
+     * only if it is exactly the last modified time of the content.</p>
+     *
+     * @param request the request
+     * @param source the content
+     * @return whether the ranges should be sent, or the whole content otherwise
+     */
+    protected boolean passIfRange(HttpServletRequest request, SeekableContentSource source)
+    {
+        String ifRange = request.getHeader(HttpHeader.IF_RANGE.asString());
+        if (ifRange == null)
+            return true;
+        ifRange = ifRange.trim();
+
+        if (ifRange.startsWith("\"") || ifRange.startsWith("W/"))
+        {
+            String etag = source.getETag();
+            return etag != null && !etag.startsWith("W/") && etag.equals(ifRange);
+        }
+
+        long lastModified = source.getLastModified();
+        if (lastModified < 0)
+            return false;
+        long date = DateParser.parseDate(ifRange);
+        return date >= 0 && date / 1000 == lastModified / 1000;
+    }
+}
@@ -0,0 +1,397 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.Function;
+import javax.servlet.AsyncContext;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that limits the rate of requests per key.</p>
+ * <p>Requests are grouped by a key extracted from the request by a
+ * {@link #setKeyExtractor(Function) key extractor}, by default the remote
+ * IP address of the connection; requests for which the key extractor returns
+ * {@code null} are not rate limited.</p>
+ * <p>Two algorithms are supported:</p>
+ * <ul>
+ * <li>{@link Algorithm#TOKEN_BUCKET}: requests are allowed up to a
+ * {@link #setBurst(int) burst} size, and the bucket refills at the configured
+ * {@link #setRate(double) rate}; requests in excess are rejected.</li>
+ * <li>{@link Algorithm#LEAKY_BUCKET}: requests are allowed at exactly the
+ * configured rate; requests in excess are asynchronously delayed, as long as
+ * at most {@link #setBurst(int) burst} requests are waiting, otherwise they
+ * are rejected.</li>
+ * </ul>
+ * <p>Rejected requests receive a {@code 429} response with a
+ * {@code Retry-After} header.</p>
+ */
+@ManagedObject("Rate limiting handler")
+public class RateLimitHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RateLimitHandler.class);
+    private static final String DELAYED = RateLimitHandler.class.getName() + ".delayed";
+
+    private final ConcurrentMap<String, Bucket> _buckets = new ConcurrentHashMap<>();
+    private final LongAdder _allowed = new LongAdder();
+    private final LongAdder _delayed = new LongAdder();
+    private final LongAdder _rejected = new LongAdder();
+    private Function<Request, String> _keyExtractor = remoteAddress();
+    private Algorithm _algorithm = Algorithm.TOKEN_BUCKET;
+    private double _rate = 10;
+    private int _burst = 10;
+    private int _maxKeys = 10_000;
+
+    @ManagedAttribute("The rate limiting algorithm")
+    public Algorithm getAlgorithm()
+    {
+        return _algorithm;
+    }
+
+    public void setAlgorithm(Algorithm algorithm)
+    {
+        _algorithm = Objects.requireNonNull(algorithm);
+        _buckets.clear();
+    }
+
+    @ManagedAttribute("The number of requests per second allowed for each key")
+    public double getRate()
+    {
+        return _rate;
+    }
+
+    public void setRate(double rate)
+    {
+        if (rate <= 0)
+            throw new IllegalArgumentException("Invalid rate " + rate);
+        _rate = rate;
+        _buckets.clear();
+    }
+
+    @ManagedAttribute("The max number of requests in a burst (token bucket) or waiting (leaky bucket) for each key")
+    public int getBurst()
+    {
+        return _burst;
+    }
+
+    public void setBurst(int burst)
+    {
+        if (burst <= 0)
+            throw new IllegalArgumentException("Invalid burst " + burst);
+        _burst = burst;
+        _buckets.clear();
+    }
+
+    @ManagedAttribute("The max number of keys tracked before idle keys are evicted")
+    public int getMaxKeys()
+    {
+        return _maxKeys;
+    }
+
+    public void setMaxKeys(int maxKeys)
+    {
+        _maxKeys = maxKeys;
+    }
+
+    public Function<Request, String> getKeyExtractor()
+    {
+        return _keyExtractor;
+    }
+
+    /**
+     * @param keyExtractor the function that extracts the rate limiting key from the request,
+     * returning {@code null} if the request must not be rate limited
+     * @see #remoteAddress()
+     * @see #header(String)
+     * @see #path()
+     */
+    public void setKeyExtractor(Function<Request, String> keyExtractor)
+    {
+        _keyExtractor = Objects.requireNonNull(keyExtractor);
+    }
+
+    @ManagedAttribute("The number of keys currently tracked")
+    public int getKeys()
+    {
+        return _buckets.size();
+    }
+
+    @ManagedAttribute("The number of requests allowed")
+    public long getRequestsAllowed()
+    {
+        return _allowed.sum();
+    }
+
+    @ManagedAttribute("The number of requests delayed")
+    public long getRequestsDelayed()
+    {
+        return _delayed.sum();
+    }
+
+    @ManagedAttribute("The number of requests rejected")
+    public long getRequestsRejected()
+    {
+        return _rejected.sum();
+    }
+
+    @ManagedOperation("Resets the statistics")
+    public void resetStatistics()
+    {
+        _allowed.reset();
+        _delayed.reset();
+        _rejected.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // A request delayed by the leaky bucket has already been accounted.
+        if (baseRequest.getAttribute(DELAYED) != null)
+        {
+            baseRequest.removeAttribute(DELAYED);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        String key = _keyExtractor.apply(baseRequest);
+        if (key == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        long now = NanoTime.now();
+        Bucket bucket = getBucket(key, now);
+        long delay = bucket.acquire(now);
+        if (delay == 0)
+        {
+            _allowed.increment();
+            super.handle(target, baseRequest, request, response);
+        }
+        else if (delay > 0)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Delaying {}ns {} for {}", delay, baseRequest, key);
+            _delayed.increment();
+            AsyncContext asyncContext = baseRequest.startAsync();
+            asyncContext.setTimeout(0);
+            baseRequest.setAttribute(DELAYED, key);
+            baseRequest.getHttpChannel().getScheduler().schedule(asyncContext::dispatch, delay, TimeUnit.NANOSECONDS);
+        }
+        else
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejecting {} for {}", baseRequest, key);
+            _rejected.increment();
+            baseRequest.setHandled(true);
+            long retryAfter = Math.max(1, TimeUnit.NANOSECONDS.toSeconds(-delay + TimeUnit.SECONDS.toNanos(1) - 1));
+            response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(retryAfter));
+            response.sendError(HttpStatus.TOO_MANY_REQUESTS_429);
+        }
+    }
+
+    private Bucket getBucket(String key, long now)
+    {
+        Bucket bucket = _buckets.get(key);
+        if (bucket != null)
+            return bucket;
+        if (_buckets.size() >= _maxKeys)
+            _buckets.values().removeIf(b -> b.isIdle(now));
+        return _buckets.computeIfAbsent(key, k -> newBucket());
+    }
+
+    private Bucket newBucket()
+    {
+        long interval = (long)(TimeUnit.SECONDS.toNanos(1) / _rate);
+        switch (_algorithm)
+        {
+            case TOKEN_BUCKET:
+                return new TokenBucket(interval, _burst);
+            case LEAKY_BUCKET:
+                return new LeakyBucket(interval, _burst);
+            default:
+                throw new IllegalStateException();
+        }
+    }
+
+    /**
+     * @return a key extractor that returns the remote IP address of the connection
+     */
+    public static Function<Request, String> remoteAddress()
+    {
+        return request ->
+        {
+            InetSocketAddress address = request.getHttpChannel().getRemoteAddress();
+            if (address == null || address.getAddress() == null)
+                return null;
+            return address.getAddress().getHostAddress();
+        };
+    }
+
+    /**
+     * @param name the request header name
+     * @return a key extractor that returns the value of the given request header
+     */
+    public static Function<Request, String> header(String name)
+    {
+        return request -> request.getHeader(name);
+    }
+
+    /**
+     * @return a key extractor that returns the request path within the context
+     */
+    public static Function<Request, String> path()
+    {
+        return Request::getPathInContext;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,rate=%s/s,burst=%d]", getClass().getSimpleName(), hashCode(), _algorithm, _rate, _burst);
+    }
+
+    /**
+     * The rate limiting algorithms.
+     */
+    public enum Algorithm
+    {
+        /**
+         * Allows bursts, rejects requests in excess.
+         */
+        TOKEN_BUCKET,
+        /**
+         * Smooths the request rate, delaying requests in excess.
+         */
+        LEAKY_BUCKET
+    }
+
+    private abstract static class Bucket
+    {
+        protected final AutoLock _lock = new AutoLock();
+        protected final long _interval;
+        protected final int _capacity;
+
+        private Bucket(long interval, int capacity)
+        {
+            _interval = interval;
+            _capacity = capacity;
+        }
+
+        /**
+         * @param now the current nanoTime
+         * @return 0 if the request is allowed, a positive delay in nanoseconds if the
+         * request must be delayed, a negative delay in nanoseconds after which the
+         * request may be retried if the request is rejected
+         */
+        abstract long acquire(long now);
+
+        abstract boolean isIdle(long now);
+    }
+
+    private static class TokenBucket extends Bucket
+    {
+        // The nanoTime at which the bucket becomes full again;
+        // each request moves it forward by one interval.
+        private long _full;
+
+        private TokenBucket(long interval, int capacity)
+        {
+            super(interval, capacity);
+            _full = NanoTime.now();
+        }
+
+        @Override
+        long acquire(long now)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                long full = NanoTime.isBefore(_full, now) ? now : _full;
+                long next = full + _interval;
+                long empty = now + _interval * _capacity;
+                if (NanoTime.isBefore(empty, next))
+                    return -Math.max(1, NanoTime.elapsed(empty, next));
+                _full = next;
+                return 0;
+            }
+        }
+
+        @Override
+        boolean isIdle(long now)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return NanoTime.isBeforeOrSame(_full, now);
+            }
+        }
+    }
+
+    private static class LeakyBucket extends Bucket
+    {
+        // The nanoTime at which the next request may be processed.
+        private long _next;
+
+        private LeakyBucket(long interval, int capacity)
+        {
+            super(interval, capacity);
+            _next = NanoTime.now();
+        }
+
+        @Override
+        long acquire(long now)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (NanoTime.isBeforeOrSame(_next, now))
+                {
+                    _next = now + _interval;
+                    return 0;
+                }
+                long delay = NanoTime.elapsed(now, _next);
+                if (delay > _interval * _capacity)
+                    return -(delay - _interval * _capacity);
+                _next += _interval;
+                return delay;
+            }
+        }
+
+        @Override
+        boolean isIdle(long now)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return NanoTime.isBeforeOrSame(_next, now);
+            }
+        }
+    }
+}
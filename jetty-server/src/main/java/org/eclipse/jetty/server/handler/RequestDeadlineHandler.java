@@ -0,0 +1,310 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that bounds the total duration of a request/response exchange.</p>
+ * <p>Idle timeouts are reset every time some bytes are read or written, so they
+ * do not bound how long a request may take. This handler enforces a wall-clock
+ * {@link #setDeadline(long) deadline}, measured from the time the request
+ * headers were received (see {@link Request#getTimeStamp()}) until the
+ * response is complete, including asynchronous processing.</p>
+ * <p>When the deadline expires:</p>
+ * <ul>
+ * <li>if the response is not committed and the request is waiting for asynchronous
+ * processing, a {@code 503} error is sent and the {@link AsyncListener}s are notified;</li>
+ * <li>if the response is not committed and the request is still being handled, for example
+ * by a handler blocked in a read or in a slow call, the exchange is aborted: an error response
+ * could only be written by the handling thread, after it returns, so it would not bound the
+ * exchange, and the response cannot be safely modified concurrently with the handling thread;</li>
+ * <li>if the response is committed, the exchange is aborted, which resets the
+ * stream for multiplexed protocols and closes the connection for HTTP/1.1.</li>
+ * </ul>
+ * <p>If a {@link #setGracePeriod(long) grace period} is configured, a committed
+ * response is given an extra grace period after the deadline, and is further
+ * extended by one grace period every time it wrote content during the previous
+ * one, so that long streaming responses that are making progress are not aborted.</p>
+ * <p>This handler can be used server wide, or within a context when different
+ * parts of an application need different deadlines.</p>
+ */
+@ManagedObject("Request deadline handler")
+public class RequestDeadlineHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RequestDeadlineHandler.class);
+    private static final String DEADLINE_ATTRIBUTE = RequestDeadlineHandler.class.getName() + ".deadline";
+
+    private final LongAdder _expired = new LongAdder();
+    private final LongAdder _aborted = new LongAdder();
+    private final LongAdder _extended = new LongAdder();
+    private long _deadline = 30000;
+    private long _gracePeriod;
+
+    /**
+     * @return the max duration of a request/response exchange in milliseconds
+     */
+    @ManagedAttribute("The max duration of a request/response exchange in ms")
+    public long getDeadline()
+    {
+        return _deadline;
+    }
+
+    /**
+     * @param deadline the max duration of a request/response exchange in milliseconds,
+     * or a non-positive value to disable the deadline
+     */
+    public void setDeadline(long deadline)
+    {
+        _deadline = deadline;
+    }
+
+    /**
+     * @return the grace period in milliseconds given to committed responses that make progress
+     */
+    @ManagedAttribute("The grace period in ms given to committed responses that make progress")
+    public long getGracePeriod()
+    {
+        return _gracePeriod;
+    }
+
+    /**
+     * @param gracePeriod the grace period in milliseconds given to committed responses
+     * that make progress, or a non-positive value to abort them as soon as the deadline expires
+     */
+    public void setGracePeriod(long gracePeriod)
+    {
+        _gracePeriod = gracePeriod;
+    }
+
+    @ManagedAttribute("The number of requests that expired before the response was committed")
+    public long getExpiredCount()
+    {
+        return _expired.sum();
+    }
+
+    @ManagedAttribute("The number of requests aborted after the response was committed")
+    public long getAbortedCount()
+    {
+        return _aborted.sum();
+    }
+
+    @ManagedAttribute("The number of grace periods given to responses making progress")
+    public long getExtendedCount()
+    {
+        return _extended.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _expired.reset();
+        _aborted.reset();
+        _extended.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // Asynchronous re-dispatches and nested usages are covered by the first deadline.
+        long deadline = getDeadline();
+        if (deadline <= 0 || baseRequest.getAttribute(DEADLINE_ATTRIBUTE) != null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        long remaining = deadline - (System.currentTimeMillis() - baseRequest.getTimeStamp());
+        if (remaining <= 0)
+        {
+            _expired.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("Deadline expired before handling {}", baseRequest);
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.SERVICE_UNAVAILABLE_503, "Request deadline exceeded");
+            return;
+        }
+
+        Deadline task = new Deadline(baseRequest);
+        baseRequest.setAttribute(DEADLINE_ATTRIBUTE, task);
+        task.schedule(remaining);
+        try
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (request.isAsyncStarted())
+                request.getAsyncContext().addListener(task);
+            else
+                task.cancel();
+        }
+    }
+
+    /**
+     * <p>Invoked when the deadline expires before the response is committed.</p>
+     * <p>By default, a {@code 503} error is sent if the request is waiting for
+     * asynchronous processing, otherwise the exchange is aborted.</p>
+     *
+     * @param baseRequest the request whose deadline expired
+     */
+    protected void onDeadlineExpired(Request baseRequest)
+    {
+        _expired.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Deadline expired for {}", baseRequest);
+
+        HttpChannelState state = baseRequest.getHttpChannelState();
+        if (state.getState() == HttpChannelState.State.WAITING)
+        {
+            // Let the error be handled by the async machinery, notifying the AsyncListeners.
+            state.asyncError(new BadMessageException(HttpStatus.SERVICE_UNAVAILABLE_503, "Request deadline exceeded"));
+            return;
+        }
+
+        // The request is being handled by another thread, that owns the response.
+        abort(baseRequest);
+    }
+
+    /**
+     * <p>Invoked when the deadline, and any grace period, expires after the response is committed.</p>
+     * <p>By default, the exchange is aborted.</p>
+     *
+     * @param baseRequest the request whose deadline expired
+     */
+    protected void onDeadlineExpiredCommitted(Request baseRequest)
+    {
+        _aborted.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Deadline expired for committed {}", baseRequest);
+        abort(baseRequest);
+    }
+
+    private void abort(Request baseRequest)
+    {
+        baseRequest.getHttpChannel().abort(new TimeoutException("Request deadline exceeded"));
+    }
+
+    private class Deadline implements Runnable, AsyncListener
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final Request _request;
+        private Scheduler.Task _task;
+        private boolean _done;
+        private long _written = -1;
+
+        private Deadline(Request request)
+        {
+            _request = request;
+        }
+
+        private void schedule(long delay)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_done)
+                    return;
+                _task = _request.getHttpChannel().getScheduler().schedule(this, delay, TimeUnit.MILLISECONDS);
+            }
+        }
+
+        private void cancel()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                _done = true;
+                if (_task != null)
+                    _task.cancel();
+            }
+        }
+
+        @Override
+        public void run()
+        {
+            Response response = _request.getResponse();
+            boolean committed;
+            try (AutoLock l = _lock.lock())
+            {
+                if (_done)
+                    return;
+
+                committed = response.isCommitted();
+                if (committed)
+                {
+                    long gracePeriod = getGracePeriod();
+                    long written = response.getHttpOutput().getWritten();
+                    // The first grace period is given unconditionally, the following ones only after progress.
+                    if (gracePeriod > 0 && written > _written)
+                    {
+                        _written = written;
+                        _extended.increment();
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Grace period of {} ms for {} after {} bytes", gracePeriod, _request, written);
+                        _task = _request.getHttpChannel().getScheduler().schedule(this, gracePeriod, TimeUnit.MILLISECONDS);
+                        return;
+                    }
+                }
+                _done = true;
+            }
+
+            if (committed)
+                onDeadlineExpiredCommitted(_request);
+            else
+                onDeadlineExpired(_request);
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            cancel();
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+    }
+}
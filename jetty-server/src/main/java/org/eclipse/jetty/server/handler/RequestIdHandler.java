@@ -0,0 +1,374 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.Objects;
+import java.util.UUID;
+import java.util.concurrent.ThreadLocalRandom;
+import javax.servlet.ServletException;
+import javax.servlet.ServletRequest;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.Loader;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.MDC;
+
+/**
+ * <p>Handler that assigns a unique ID to each request.</p>
+ * <p>The request ID is generated by a {@link Generator}, by default
+ * a {@link Generator#ulid() ULID}, or it is taken from the
+ * {@link #getRequestHeader() request header} when
+ * {@link #isTrustRequestHeader() trusted}, for example when the request
+ * ID has been assigned by an upstream proxy or service.</p>
+ * <p>The request ID is:</p>
+ * <ul>
+ *   <li>stored as the {@value #REQUEST_ID_ATTRIBUTE} request attribute,
+ *   see also {@link #getRequestId(ServletRequest)}, so that it can be logged
+ *   by {@code CustomRequestLog} with the format {@code %{org.eclipse.jetty.server.requestId}ra}</li>
+ *   <li>echoed in the {@link #getResponseHeader() response header}</li>
+ *   <li>put in the SLF4J {@link MDC} under the {@link #getMdcKey() MDC key}
+ *   while the request is handled, so that it can be logged by the application</li>
+ * </ul>
+ * <p>The MDC is also what allows {@code org.eclipse.jetty.client.util.RequestIdInterceptor}
+ * to forward the request ID to downstream services called with {@code HttpClient}
+ * while handling the request.</p>
+ */
+@ManagedObject("Request ID handler")
+public class RequestIdHandler extends HandlerWrapper
+{
+    public static final String REQUEST_ID_ATTRIBUTE = "org.eclipse.jetty.server.requestId";
+    public static final String DEFAULT_HEADER = "X-Request-Id";
+    public static final String DEFAULT_MDC_KEY = "requestId";
+
+    private Generator _generator = Generator.ulid();
+    private String _requestHeader = DEFAULT_HEADER;
+    private String _responseHeader = DEFAULT_HEADER;
+    private String _mdcKey = DEFAULT_MDC_KEY;
+    private boolean _trustRequestHeader;
+    private int _maxRequestIdLength = 128;
+
+    /**
+     * @param request the request
+     * @return the ID assigned to the request, or null if the request has not been handled by a RequestIdHandler
+     */
+    public static String getRequestId(ServletRequest request)
+    {
+        Object requestId = request.getAttribute(REQUEST_ID_ATTRIBUTE);
+        return requestId instanceof String ? (String)requestId : null;
+    }
+
+    /**
+     * @return the request ID generator
+     */
+    public Generator getGenerator()
+    {
+        return _generator;
+    }
+
+    /**
+     * @param generator the request ID generator
+     */
+    public void setGenerator(Generator generator)
+    {
+        _generator = Objects.requireNonNull(generator);
+    }
+
+    /**
+     * <p>Sets the request ID generator by type, either {@code ULID}, {@code UUIDv7}, {@code UUID}
+     * or the fully qualified name of a {@link Generator} class with a public no-arguments constructor.</p>
+     *
+     * @param type the request ID generator type
+     * @throws Exception if the generator class cannot be instantiated
+     */
+    public void setGeneratorType(String type) throws Exception
+    {
+        switch (type)
+        {
+            case "ULID":
+                setGenerator(Generator.ulid());
+                break;
+            case "UUIDv7":
+                setGenerator(Generator.uuidV7());
+                break;
+            case "UUID":
+                setGenerator(Generator.uuid());
+                break;
+            default:
+                setGenerator((Generator)Loader.loadClass(type).getConstructor().newInstance());
+                break;
+        }
+    }
+
+    /**
+     * @return the name of the request header that may carry the request ID, or null
+     */
+    @ManagedAttribute("The request header that may carry the request ID")
+    public String getRequestHeader()
+    {
+        return _requestHeader;
+    }
+
+    /**
+     * @param requestHeader the name of the request header that may carry the request ID, or null
+     */
+    public void setRequestHeader(String requestHeader)
+    {
+        _requestHeader = requestHeader;
+    }
+
+    /**
+     * @return whether the request ID is taken from the request header, if present and valid
+     */
+    @ManagedAttribute("Whether the request ID is taken from the request header")
+    public boolean isTrustRequestHeader()
+    {
+        return _trustRequestHeader;
+    }
+
+    /**
+     * <p>Only requests coming from trusted clients, typically reverse proxies or
+     * services of the same system, should be allowed to choose their request ID.</p>
+     *
+     * @param trustRequestHeader whether the request ID is taken from the request header, if present and valid
+     */
+    public void setTrustRequestHeader(boolean trustRequestHeader)
+    {
+        _trustRequestHeader = trustRequestHeader;
+    }
+
+    /**
+     * @return the max length of a request ID taken from the request header
+     */
+    @ManagedAttribute("The max length of a request ID taken from the request header")
+    public int getMaxRequestIdLength()
+    {
+        return _maxRequestIdLength;
+    }
+
+    /**
+     * @param maxRequestIdLength the max length of a request ID taken from the request header
+     */
+    public void setMaxRequestIdLength(int maxRequestIdLength)
+    {
+        _maxRequestIdLength = maxRequestIdLength;
+    }
+
+    /**
+     * @return the name of the response header that echoes the request ID, or null
+     */
+    @ManagedAttribute("The response header that echoes the request ID")
+    public String getResponseHeader()
+    {
+        return _responseHeader;
+    }
+
+    /**
+     * @param responseHeader the name of the response header that echoes the request ID, or null to not echo the request ID
+     */
+    public void setResponseHeader(String responseHeader)
+    {
+        _responseHeader = responseHeader;
+    }
+
+    /**
+     * @return the MDC key of the request ID, or null
+     */
+    @ManagedAttribute("The MDC key of the request ID")
+    public String getMdcKey()
+    {
+        return _mdcKey;
+    }
+
+    /**
+     * @param mdcKey the MDC key of the request ID, or null to not put the request ID in the MDC
+     */
+    public void setMdcKey(String mdcKey)
+    {
+        _mdcKey = mdcKey;
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        // Asynchronous dispatches of the same request keep the same ID.
+        String requestId = getRequestId(baseRequest);
+        if (requestId == null)
+        {
+            requestId = newRequestId(baseRequest);
+            baseRequest.setAttribute(REQUEST_ID_ATTRIBUTE, requestId);
+            String responseHeader = getResponseHeader();
+            if (responseHeader != null)
+                response.setHeader(responseHeader, requestId);
+        }
+
+        String mdcKey = getMdcKey();
+        if (mdcKey == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        String previous = MDC.get(mdcKey);
+        MDC.put(mdcKey, requestId);
+        try
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (previous == null)
+                MDC.remove(mdcKey);
+            else
+                MDC.put(mdcKey, previous);
+        }
+    }
+
+    protected String newRequestId(Request request)
+    {
+        String requestHeader = getRequestHeader();
+        if (isTrustRequestHeader() && requestHeader != null)
+        {
+            String requestId = request.getHeader(requestHeader);
+            if (isValidRequestId(requestId))
+                return requestId;
+        }
+        return getGenerator().newRequestId(request);
+    }
+
+    /**
+     * <p>Returns whether the given request ID, taken from the request header, is valid:
+     * only non empty strings of at most {@link #getMaxRequestIdLength()} visible ASCII
+     * characters are valid, so that the request ID can be safely echoed and logged.</p>
+     *
+     * @param requestId the request ID to validate
+     * @return whether the request ID is valid
+     */
+    protected boolean isValidRequestId(String requestId)
+    {
+        if (requestId == null || requestId.isEmpty() || requestId.length() > getMaxRequestIdLength())
+            return false;
+        for (int i = 0; i < requestId.length(); ++i)
+        {
+            char c = requestId.charAt(i);
+            if (c <= 0x20 || c >= 0x7F)
+                return false;
+        }
+        return true;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,%s}", getClass().getSimpleName(), hashCode(), _generator, _responseHeader);
+    }
+
+    /**
+     * <p>Generates request IDs.</p>
+     * <p>Implementations must be thread-safe.</p>
+     */
+    @FunctionalInterface
+    public interface Generator
+    {
+        /**
+         * @param request the request
+         * @return a new unique request ID
+         */
+        public String newRequestId(Request request);
+
+        /**
+         * @return a generator of <a href="https://github.com/ulid/spec">ULID</a>s,
+         * 26 characters lexicographically sortable IDs
+         */
+        public static Generator ulid()
+        {
+            return new ULIDGenerator();
+        }
+
+        /**
+         * @return a generator of version 7, time ordered, UUIDs, as defined in RFC 9562
+         */
+        public static Generator uuidV7()
+        {
+            return new UUIDv7Generator();
+        }
+
+        /**
+         * @return a generator of version 4, random, UUIDs
+         */
+        public static Generator uuid()
+        {
+            return request -> UUID.randomUUID().toString();
+        }
+    }
+
+    private static class ULIDGenerator implements Generator
+    {
+        private static final char[] ALPHABET = "0123456789ABCDEFGHJKMNPQRSTVWXYZ".toCharArray();
+
+        @Override
+        public String newRequestId(Request request)
+        {
+            ThreadLocalRandom random = ThreadLocalRandom.current();
+            long time = System.currentTimeMillis();
+            long randomHigh = random.nextLong() & 0xFFFF;
+            long randomLow = random.nextLong();
+            char[] chars = new char[26];
+            // 48 bits of time, encoded in 10 characters of 5 bits (the first has only 3 bits).
+            for (int i = 9; i >= 0; --i)
+            {
+                chars[i] = ALPHABET[(int)(time & 0x1F)];
+                time >>>= 5;
+            }
+            // 80 bits of randomness, encoded in 16 characters of 5 bits.
+            for (int i = 25; i >= 10; --i)
+            {
+                chars[i] = ALPHABET[(int)(randomLow & 0x1F)];
+                randomLow = (randomLow >>> 5) | ((randomHigh & 0x1F) << 59);
+                randomHigh >>>= 5;
+            }
+            return new String(chars);
+        }
+
+        @Override
+        public String toString()
+        {
+            return "ULID";
+        }
+    }
+
+    private static class UUIDv7Generator implements Generator
+    {
+        @Override
+        public String newRequestId(Request request)
+        {
+            ThreadLocalRandom random = ThreadLocalRandom.current();
+            long time = System.currentTimeMillis();
+            // 48 bits of time, 4 bits of version, 12 random bits.
+            long mostSignificantBits = (time << 16) | 0x7000 | (random.nextInt() & 0x0FFF);
+            // 2 bits of variant, 62 random bits.
+            long leastSignificantBits = (random.nextLong() & 0x3FFFFFFFFFFFFFFFL) | 0x8000000000000000L;
+            return new UUID(mostSignificantBits, leastSignificantBits).toString();
+        }
+
+        @Override
+        public String toString()
+        {
+            return "UUIDv7";
+        }
+    }
+}
@@ -19,6 +19,7 @@ import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.RequestLog;
 import org.eclipse.jetty.server.Server;
@@ -43,7 +44,7 @@ public class RequestLogHandler extends HandlerWrapper
         if (baseRequest.getDispatcherType() == DispatcherType.REQUEST)
             baseRequest.getHttpChannel().addRequestLog(_requestLog);
         if (_handler != null)
-            _handler.handle(target, baseRequest, request, response);
+            HandlerInstrumentation.handle(_handler, target, baseRequest, request, response);
     }
 
     public void setRequestLog(RequestLog requestLog)
@@ -17,16 +17,20 @@ import java.io.FileNotFoundException;
 import java.io.IOException;
 import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.LinkedHashSet;
 import java.util.List;
+import java.util.Set;
 import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.CompressedContentFormat;
+import org.eclipse.jetty.http.HttpContent;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http.MimeTypes;
 import org.eclipse.jetty.http.PreEncodedHttpField;
+import org.eclipse.jetty.server.CompressedContentCache;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.ResourceContentFactory;
 import org.eclipse.jetty.server.ResourceService;
@@ -44,6 +48,11 @@ import org.slf4j.LoggerFactory;
  *
  * This handle will serve static content and handle If-Modified-Since headers. No caching is done. Requests for resources that do not exist are let pass (Eg no
  * 404's).
+ * <p>
+ * Precompressed sibling resources (for example {@code file.js.br}, {@code file.js.gz} or {@code file.js.zst})
+ * are served, depending on the request {@code Accept-Encoding}, when {@link #setPrecompressed(boolean) enabled}.
+ * Compressed variants of frequently requested resources may also be generated and cached in memory
+ * by configuring a {@link #setCompressedContentCache(CompressedContentCache) CompressedContentCache}.
  */
 public class ResourceHandler extends HandlerWrapper implements ResourceFactory, WelcomeFactory
 {
@@ -54,6 +63,7 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
     Resource _defaultStylesheet;
     MimeTypes _mimeTypes;
     private final ResourceService _resourceService;
+    private CompressedContentCache _compressedContentCache;
     Resource _stylesheet;
     String[] _welcomes = {"index.html"};
 
@@ -99,12 +109,32 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         if (_mimeTypes == null)
             _mimeTypes = _context == null ? new MimeTypes() : _context.getMimeTypes();
 
-        _resourceService.setContentFactory(new ResourceContentFactory(this, _mimeTypes, _resourceService.getPrecompressedFormats()));
+        HttpContent.ContentFactory contentFactory = new ResourceContentFactory(this, _mimeTypes, _resourceService.getPrecompressedFormats());
+        CompressedContentCache compressedContentCache = getCompressedContentCache();
+        if (compressedContentCache != null)
+        {
+            if (compressedContentCache.getExecutor() == null && getServer() != null)
+                compressedContentCache.setExecutor(getServer().getThreadPool());
+            // The generated variants are only served if their formats are precompressed formats.
+            Set<CompressedContentFormat> formats = new LinkedHashSet<>(Arrays.asList(_resourceService.getPrecompressedFormats()));
+            formats.addAll(Arrays.asList(compressedContentCache.getCompressedContentFormats()));
+            _resourceService.setPrecompressedFormats(formats.toArray(new CompressedContentFormat[0]));
+            contentFactory = compressedContentCache.wrap(contentFactory);
+        }
+        _resourceService.setContentFactory(contentFactory);
         _resourceService.setWelcomeFactory(this);
 
         super.doStart();
     }
 
+    /**
+     * @return the ResourceService that serves the resources
+     */
+    protected ResourceService getResourceService()
+    {
+        return _resourceService;
+    }
+
     /**
      * @return Returns the resourceBase.
      */
@@ -131,6 +161,11 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         return _resourceService.getGzipEquivalentFileExtensions();
     }
 
+    public List<String> getAttachmentFileExtensions()
+    {
+        return _resourceService.getAttachmentFileExtensions();
+    }
+
     public MimeTypes getMimeTypes()
     {
         return _mimeTypes;
@@ -274,6 +309,23 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         return _resourceService.isEtags();
     }
 
+    /**
+     * @return the cache of compressed variants generated on the fly, or null if none
+     */
+    public CompressedContentCache getCompressedContentCache()
+    {
+        return _compressedContentCache;
+    }
+
+    /**
+     * @param compressedContentCache the cache of compressed variants generated on the fly, or null for none
+     */
+    public void setCompressedContentCache(CompressedContentCache compressedContentCache)
+    {
+        updateBean(_compressedContentCache, compressedContentCache);
+        _compressedContentCache = compressedContentCache;
+    }
+
     /**
      * @return Precompressed resources formats that can be used to serve compressed variant of resources.
      */
@@ -298,6 +350,30 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         return _resourceService.isRedirectWelcome();
     }
 
+    /**
+     * @return the min content length of files sent without copying them to user-space buffers, or -1 if disabled
+     */
+    public long getSendFileThreshold()
+    {
+        return _resourceService.getSendFileThreshold();
+    }
+
+    /**
+     * @return the number of responses sent without copying the file content to user-space buffers
+     */
+    public long getSendFileResponses()
+    {
+        return _resourceService.getSendFileResponses();
+    }
+
+    /**
+     * @return the number of bytes sent without copying them to user-space buffers
+     */
+    public long getSendFileBytes()
+    {
+        return _resourceService.getSendFileBytes();
+    }
+
     /**
      * @param acceptRanges If true, range requests and responses are supported
      */
@@ -357,6 +433,16 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         _resourceService.setGzipEquivalentFileExtensions(gzipEquivalentFileExtensions);
     }
 
+    /**
+     * @param attachmentFileExtensions the file extensions of the resources sent with a
+     * {@code Content-Disposition: attachment} header, or {@code "*"} for all the resources
+     * @see ResourceService#setAttachmentFileExtensions(List)
+     */
+    public void setAttachmentFileExtensions(List<String> attachmentFileExtensions)
+    {
+        _resourceService.setAttachmentFileExtensions(attachmentFileExtensions);
+    }
+
     /**
      * @param precompressedFormats The list of precompresed formats to serve in encoded format if matching resource found.
      * For example serve gzip encoded file if ".gz" suffixed resource is found.
@@ -366,6 +452,18 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         _resourceService.setPrecompressedFormats(precompressedFormats);
     }
 
+    /**
+     * @param precompressed true to serve the brotli, zstd and gzip precompressed siblings of resources
+     * (with {@code .br}, {@code .zst} and {@code .gz} extensions), false to not serve precompressed resources
+     * @see #setPrecompressedFormats(CompressedContentFormat[])
+     */
+    public void setPrecompressed(boolean precompressed)
+    {
+        setPrecompressedFormats(precompressed
+            ? new CompressedContentFormat[]{CompressedContentFormat.BR, CompressedContentFormat.ZSTD, CompressedContentFormat.GZIP}
+            : new CompressedContentFormat[0]);
+    }
+
     public void setMimeTypes(MimeTypes mimeTypes)
     {
         _mimeTypes = mimeTypes;
@@ -389,6 +487,16 @@ public class ResourceHandler extends HandlerWrapper implements ResourceFactory,
         _resourceService.setRedirectWelcome(redirectWelcome);
     }
 
+    /**
+     * @param sendFileThreshold the min content length of files sent without copying them
+     * to user-space buffers, or -1 to disable zero-copy
+     * @see ResourceService#setSendFileThreshold(long)
+     */
+    public void setSendFileThreshold(long sendFileThreshold)
+    {
+        _resourceService.setSendFileThreshold(sendFileThreshold);
+    }
+
     /**
      * @param resourceBase The base resource as a string.
      */
@@ -0,0 +1,419 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.io.BandwidthLimiter;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.HttpInput;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that enforces resource quotas on the requests of a context.</p>
+ * <p>This handler is meant to be inserted in the handler chain of each
+ * {@link ContextHandler}, for example with
+ * {@link ContextHandler#insertHandler(HandlerWrapper)}, so that each context
+ * of a multi-tenant server has its own quotas.</p>
+ * <p>The following quotas are supported, each disabled when its value is
+ * not positive:</p>
+ * <ul>
+ * <li>{@link #setMaxConcurrentRequests(int) max concurrent requests}: the max
+ * number of requests, including asynchronous ones, handled concurrently.</li>
+ * <li>{@link #setMaxRequestContentBytes(long) max request content bytes}: the
+ * max aggregate request content of the concurrent requests, counted as it is read
+ * (or, when known, from the {@code Content-Length} header) and released when the
+ * request completes.</li>
+ * <li>{@link #setMaxMultiPartBytes(long) max multipart bytes}: as above, but for
+ * {@code multipart/form-data} requests, whose parts may be written to temporary
+ * files; the multipart content is an upper bound of the temporary disk usage.</li>
+ * <li>{@link #setMaxOutputRate(long) max output rate}: the max aggregate
+ * number of response bytes per second, with bursts of up to
+ * {@link #setMaxOutputBurst(long) max output burst} bytes; the response content
+ * is throttled like {@link BandwidthLimitHandler} does, with a {@link BandwidthLimiter}
+ * shared by all the requests of the context.</li>
+ * </ul>
+ * <p>Requests in excess of a quota receive a {@link #setRejectStatus(int)
+ * reject status} response, by default {@code 503}, with a {@code Retry-After}
+ * header; if a request content quota is exceeded while the content is read,
+ * the read fails with a {@link BadMessageException} with the reject status.</p>
+ */
+@ManagedObject("Per-context resource quota handler")
+public class ResourceQuotaHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResourceQuotaHandler.class);
+
+    private final AtomicLong _requests = new AtomicLong();
+    private final AtomicLong _contentBytes = new AtomicLong();
+    private final AtomicLong _multiPartBytes = new AtomicLong();
+    private final LongAdder _rejectedRequests = new LongAdder();
+    private final LongAdder _rejectedContent = new LongAdder();
+    private final LongAdder _rejectedMultiPart = new LongAdder();
+    private final LongAdder _delayedWrites = new LongAdder();
+    private int _maxConcurrentRequests;
+    private long _maxRequestContentBytes;
+    private long _maxMultiPartBytes;
+    private long _maxOutputRate;
+    private long _maxOutputBurst = 16 * 1024;
+    private volatile BandwidthLimiter _outputLimiter;
+    private int _rejectStatus = HttpStatus.SERVICE_UNAVAILABLE_503;
+    private int _retryAfter = 1;
+
+    @ManagedAttribute("The max number of concurrent requests")
+    public int getMaxConcurrentRequests()
+    {
+        return _maxConcurrentRequests;
+    }
+
+    public void setMaxConcurrentRequests(int maxConcurrentRequests)
+    {
+        _maxConcurrentRequests = maxConcurrentRequests;
+    }
+
+    @ManagedAttribute("The max aggregate request content bytes of concurrent requests")
+    public long getMaxRequestContentBytes()
+    {
+        return _maxRequestContentBytes;
+    }
+
+    public void setMaxRequestContentBytes(long maxRequestContentBytes)
+    {
+        _maxRequestContentBytes = maxRequestContentBytes;
+    }
+
+    @ManagedAttribute("The max aggregate multipart content bytes of concurrent requests")
+    public long getMaxMultiPartBytes()
+    {
+        return _maxMultiPartBytes;
+    }
+
+    public void setMaxMultiPartBytes(long maxMultiPartBytes)
+    {
+        _maxMultiPartBytes = maxMultiPartBytes;
+    }
+
+    @ManagedAttribute("The max aggregate response bytes per second")
+    public long getMaxOutputRate()
+    {
+        return _maxOutputRate;
+    }
+
+    public void setMaxOutputRate(long maxOutputRate)
+    {
+        _maxOutputRate = maxOutputRate;
+        updateOutputLimiter();
+    }
+
+    @ManagedAttribute("The max aggregate response bytes written in a burst")
+    public long getMaxOutputBurst()
+    {
+        return _maxOutputBurst;
+    }
+
+    public void setMaxOutputBurst(long maxOutputBurst)
+    {
+        if (maxOutputBurst <= 0)
+            throw new IllegalArgumentException("Invalid burst " + maxOutputBurst);
+        _maxOutputBurst = maxOutputBurst;
+        updateOutputLimiter();
+    }
+
+    private void updateOutputLimiter()
+    {
+        long rate = getMaxOutputRate();
+        _outputLimiter = rate > 0 ? new BandwidthLimiter(rate, getMaxOutputBurst()) : null;
+    }
+
+    @ManagedAttribute("The response status of rejected requests")
+    public int getRejectStatus()
+    {
+        return _rejectStatus;
+    }
+
+    /**
+     * @param rejectStatus the response status of rejected requests,
+     * typically {@code 503} or {@code 429}
+     */
+    public void setRejectStatus(int rejectStatus)
+    {
+        if (rejectStatus < HttpStatus.BAD_REQUEST_400 || rejectStatus > 599)
+            throw new IllegalArgumentException("Invalid reject status " + rejectStatus);
+        _rejectStatus = rejectStatus;
+    }
+
+    @ManagedAttribute("The Retry-After seconds of rejected requests")
+    public int getRetryAfter()
+    {
+        return _retryAfter;
+    }
+
+    /**
+     * @param retryAfter the {@code Retry-After} seconds of rejected requests,
+     * or a negative value to not send the {@code Retry-After} header
+     */
+    public void setRetryAfter(int retryAfter)
+    {
+        _retryAfter = retryAfter;
+    }
+
+    @ManagedAttribute("The number of concurrent requests")
+    public long getRequests()
+    {
+        return _requests.get();
+    }
+
+    @ManagedAttribute("The aggregate request content bytes of concurrent requests")
+    public long getRequestContentBytes()
+    {
+        return _contentBytes.get();
+    }
+
+    @ManagedAttribute("The aggregate multipart content bytes of concurrent requests")
+    public long getMultiPartBytes()
+    {
+        return _multiPartBytes.get();
+    }
+
+    @ManagedAttribute("The number of requests rejected by the concurrent requests quota")
+    public long getRejectedRequests()
+    {
+        return _rejectedRequests.sum();
+    }
+
+    @ManagedAttribute("The number of requests rejected by the request content quota")
+    public long getRejectedContent()
+    {
+        return _rejectedContent.sum();
+    }
+
+    @ManagedAttribute("The number of requests rejected by the multipart quota")
+    public long getRejectedMultiPart()
+    {
+        return _rejectedMultiPart.sum();
+    }
+
+    @ManagedAttribute("The number of response writes delayed by the output rate quota")
+    public long getDelayedWrites()
+    {
+        return _delayedWrites.sum();
+    }
+
+    @ManagedOperation("Resets the statistics")
+    public void resetStatistics()
+    {
+        _rejectedRequests.reset();
+        _rejectedContent.reset();
+        _rejectedMultiPart.reset();
+        _delayedWrites.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        Handler handler = getHandler();
+        if (handler == null || !isStarted())
+            return;
+
+        HttpChannelState state = baseRequest.getHttpChannelState();
+        // Only the initial dispatch is accounted.
+        if (!state.isInitial())
+        {
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
+            return;
+        }
+
+        long requests = _requests.incrementAndGet();
+        int maxRequests = getMaxConcurrentRequests();
+        if (maxRequests > 0 && requests > maxRequests)
+        {
+            _requests.decrementAndGet();
+            _rejectedRequests.increment();
+            reject(baseRequest, response, "concurrent requests");
+            return;
+        }
+
+        Quota quota = new Quota(baseRequest);
+        try
+        {
+            if (!quota.acquire(baseRequest.getContentLengthLong()))
+            {
+                reject(baseRequest, response, quota.isMultiPart() ? "multipart bytes" : "request content bytes");
+                return;
+            }
+
+            if (getMaxRequestContentBytes() > 0 || getMaxMultiPartBytes() > 0)
+                baseRequest.getHttpInput().addInterceptor(quota);
+            BandwidthLimiter outputLimiter = _outputLimiter;
+            if (outputLimiter != null)
+            {
+                HttpOutput output = baseRequest.getResponse().getHttpOutput();
+                Scheduler scheduler = baseRequest.getHttpChannel().getScheduler();
+                output.setInterceptor(new BandwidthLimitHandler.BandwidthLimitInterceptor(this, output.getInterceptor(), outputLimiter, scheduler, _delayedWrites::increment));
+            }
+
+            HandlerInstrumentation.handle(handler, target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (state.isAsyncStarted())
+                state.addListener(quota);
+            else
+                quota.release();
+        }
+    }
+
+    private void reject(Request baseRequest, HttpServletResponse response, String reason) throws IOException
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("Rejecting {}, {} quota exceeded", baseRequest, reason);
+        baseRequest.setHandled(true);
+        int retryAfter = getRetryAfter();
+        if (retryAfter >= 0)
+            response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(retryAfter));
+        response.sendError(getRejectStatus(), "Quota exceeded: " + reason);
+    }
+
+    private static boolean acquire(AtomicLong total, long amount, long max)
+    {
+        while (true)
+        {
+            long current = total.get();
+            long next = current + amount;
+            if (max > 0 && next > max)
+                return false;
+            if (total.compareAndSet(current, next))
+                return true;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[requests=%d/%d,content=%d/%d,multipart=%d/%d,rate=%d/s]",
+            getClass().getSimpleName(), hashCode(),
+            getRequests(), getMaxConcurrentRequests(),
+            getRequestContentBytes(), getMaxRequestContentBytes(),
+            getMultiPartBytes(), getMaxMultiPartBytes(),
+            getMaxOutputRate());
+    }
+
+    /**
+     * <p>The quota accounting of a single request, released when the request completes.</p>
+     */
+    private class Quota implements HttpInput.Interceptor, AsyncListener
+    {
+        private final AtomicLong _read = new AtomicLong();
+        private final AtomicLong _acquired = new AtomicLong();
+        private final boolean _multiPart;
+        private HttpInput.ErrorContent _failure;
+
+        private Quota(Request request)
+        {
+            String contentType = request.getContentType();
+            _multiPart = contentType != null && MimeTypes.Type.MULTIPART_FORM_DATA.is(HttpField.valueParameters(contentType, null));
+        }
+
+        private boolean isMultiPart()
+        {
+            return _multiPart;
+        }
+
+        private boolean acquire(long bytes)
+        {
+            if (bytes <= 0)
+                return true;
+            boolean acquired = _multiPart
+                ? ResourceQuotaHandler.acquire(_multiPartBytes, bytes, getMaxMultiPartBytes())
+                : ResourceQuotaHandler.acquire(_contentBytes, bytes, getMaxRequestContentBytes());
+            if (acquired)
+                _acquired.addAndGet(bytes);
+            else if (_multiPart)
+                _rejectedMultiPart.increment();
+            else
+                _rejectedContent.increment();
+            return acquired;
+        }
+
+        @Override
+        public HttpInput.Content readFrom(HttpInput.Content content)
+        {
+            if (_failure != null)
+                return _failure;
+            if (content.isSpecial())
+                return content;
+
+            long read = _read.addAndGet(content.remaining());
+            long excess = read - _acquired.get();
+            if (excess > 0 && !acquire(excess))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Failing request content, {} quota exceeded {}", _multiPart ? "multipart bytes" : "request content bytes", ResourceQuotaHandler.this);
+                _failure = new HttpInput.ErrorContent(new BadMessageException(getRejectStatus(), "Quota exceeded"));
+                return _failure;
+            }
+            return content;
+        }
+
+        private void release()
+        {
+            _requests.decrementAndGet();
+            long acquired = _acquired.getAndSet(0);
+            if (acquired > 0)
+                (_multiPart ? _multiPartBytes : _contentBytes).addAndGet(-acquired);
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            release();
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+    }
+}
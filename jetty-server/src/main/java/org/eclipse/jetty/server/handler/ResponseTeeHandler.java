@@ -0,0 +1,617 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.channels.FileChannel;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.nio.file.StandardOpenOption;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Objects;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.AtomicLong;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.CountingCallback;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that duplicates the response content to an audit {@link Sink},
+ * for example to record the responses of an API for compliance purposes.</p>
+ * <p>The response content is written to the client and to the sink concurrently:
+ * the sink receives a read-only view of the application buffers rather than a copy,
+ * and the application write completes when both the client write and the sink write
+ * have completed.
+ * Sinks that cannot consume the content quickly (for example because they send it to
+ * a remote message broker) should copy the content and complete the callback immediately,
+ * so that they do not slow down the client.
+ * A sink failure never fails the response: the recording is abandoned and the response
+ * continues normally.</p>
+ * <p>Only the first {@link #getMaxContentLength() max content length} bytes of the
+ * response content are recorded, and only responses whose mime type
+ * {@link #includeMimeTypes(String...) matches} are recorded.</p>
+ */
+@ManagedObject("Response tee handler")
+public class ResponseTeeHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ResponseTeeHandler.class);
+
+    private final IncludeExclude<String> _mimeTypes = new IncludeExclude<>();
+    private final AtomicLong _recordings = new AtomicLong();
+    private final AtomicLong _failures = new AtomicLong();
+    private Sink _sink;
+    private long _maxContentLength = 64 * 1024;
+
+    public ResponseTeeHandler()
+    {
+        this(null);
+    }
+
+    public ResponseTeeHandler(Sink sink)
+    {
+        _sink = sink;
+    }
+
+    public Sink getSink()
+    {
+        return _sink;
+    }
+
+    public void setSink(Sink sink)
+    {
+        updateBean(_sink, sink);
+        _sink = sink;
+    }
+
+    @ManagedAttribute("The max number of content bytes recorded for each response, or -1 for unlimited")
+    public long getMaxContentLength()
+    {
+        return _maxContentLength;
+    }
+
+    public void setMaxContentLength(long maxContentLength)
+    {
+        _maxContentLength = maxContentLength;
+    }
+
+    /**
+     * @param mimeTypes the response mime types, without parameters, that are recorded
+     */
+    public void includeMimeTypes(String... mimeTypes)
+    {
+        for (String mimeType : mimeTypes)
+        {
+            _mimeTypes.include(StringUtil.asciiToLowerCase(mimeType));
+        }
+    }
+
+    /**
+     * @param mimeTypes the response mime types, without parameters, that are not recorded
+     */
+    public void excludeMimeTypes(String... mimeTypes)
+    {
+        for (String mimeType : mimeTypes)
+        {
+            _mimeTypes.exclude(StringUtil.asciiToLowerCase(mimeType));
+        }
+    }
+
+    @ManagedAttribute("The number of responses recorded")
+    public long getRecordings()
+    {
+        return _recordings.get();
+    }
+
+    @ManagedAttribute("The number of recordings abandoned because of sink failures")
+    public long getRecordingFailures()
+    {
+        return _failures.get();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        Sink sink = getSink();
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        if (sink == null || isIntercepted(out))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        out.setInterceptor(new TeeInterceptor(baseRequest, sink, out.getInterceptor()));
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private boolean isIntercepted(HttpOutput out)
+    {
+        HttpOutput.Interceptor interceptor = out.getInterceptor();
+        while (interceptor != null)
+        {
+            if (interceptor instanceof TeeInterceptor && ((TeeInterceptor)interceptor).getHandler() == this)
+                return true;
+            interceptor = interceptor.getNextInterceptor();
+        }
+        return false;
+    }
+
+    /**
+     * @param request the request
+     * @param response the response, just before its content is written
+     * @return whether the response content should be recorded
+     */
+    protected boolean isRecorded(Request request, Response response)
+    {
+        if (_mimeTypes.isEmpty())
+            return true;
+        String contentType = response.getContentType();
+        String mimeType = contentType == null ? null : StringUtil.asciiToLowerCase(MimeTypes.getContentTypeWithoutCharset(contentType));
+        return mimeType != null && _mimeTypes.test(mimeType);
+    }
+
+    /**
+     * <p>The destination of the recorded response content.</p>
+     * <p>This is the extension point to record responses to external systems.</p>
+     */
+    public interface Sink
+    {
+        /**
+         * <p>Creates a new recording for the given response.</p>
+         * <p>This method is called just before the first content of the response is written,
+         * so the response status and headers are available.</p>
+         *
+         * @param request the request
+         * @param response the response
+         * @return a new recording, or {@code null} to not record this response
+         */
+        Recording newRecording(Request request, Response response);
+    }
+
+    /**
+     * <p>The recording of the content of a single response.</p>
+     */
+    public interface Recording
+    {
+        /**
+         * <p>Writes the given response content to this recording.</p>
+         * <p>The content buffer is read-only and belongs to the application:
+         * it must not be used after the callback has been completed.</p>
+         *
+         * @param content the response content
+         * @param last whether this is the last content of the recording, either because
+         * the response is complete or because the max content length has been reached
+         * @param callback the callback to complete when the content has been consumed
+         */
+        void write(ByteBuffer content, boolean last, Callback callback);
+
+        /**
+         * <p>Callback method invoked when the response fails before it is complete.</p>
+         *
+         * @param failure the response failure
+         */
+        default void fail(Throwable failure)
+        {
+        }
+    }
+
+    /**
+     * <p>A {@link Sink} that keeps the recorded responses in memory, mostly useful for testing.</p>
+     */
+    public static class MemorySink implements Sink
+    {
+        private final List<Recorded> _recorded = new CopyOnWriteArrayList<>();
+
+        @Override
+        public Recording newRecording(Request request, Response response)
+        {
+            Recorded recorded = new Recorded(request.getMethod(), request.getRequestURI(), response.getStatus(), response.getContentType());
+            _recorded.add(recorded);
+            return recorded;
+        }
+
+        /**
+         * @return the responses recorded so far, including the ones still in progress
+         */
+        public List<Recorded> getRecorded()
+        {
+            return new ArrayList<>(_recorded);
+        }
+
+        public void clear()
+        {
+            _recorded.clear();
+        }
+    }
+
+    /**
+     * <p>A response recorded by {@link MemorySink}.</p>
+     */
+    public static class Recorded implements Recording
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final String _method;
+        private final String _uri;
+        private final int _status;
+        private final String _contentType;
+        private final ByteArrayOutputStream _content = new ByteArrayOutputStream();
+        private boolean _complete;
+        private Throwable _failure;
+
+        private Recorded(String method, String uri, int status, String contentType)
+        {
+            _method = method;
+            _uri = uri;
+            _status = status;
+            _contentType = contentType;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                BufferUtil.writeTo(content, _content);
+                _complete = last;
+            }
+            catch (Throwable x)
+            {
+                callback.failed(x);
+                return;
+            }
+            callback.succeeded();
+        }
+
+        @Override
+        public void fail(Throwable failure)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                _failure = failure;
+            }
+        }
+
+        public String getMethod()
+        {
+            return _method;
+        }
+
+        public String getURI()
+        {
+            return _uri;
+        }
+
+        public int getStatus()
+        {
+            return _status;
+        }
+
+        public String getContentType()
+        {
+            return _contentType;
+        }
+
+        /**
+         * @return a copy of the content recorded so far
+         */
+        public byte[] getContent()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _content.toByteArray();
+            }
+        }
+
+        public String getContentAsString()
+        {
+            return new String(getContent(), StandardCharsets.UTF_8);
+        }
+
+        /**
+         * @return whether the last content of the recording has been written
+         */
+        public boolean isComplete()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _complete;
+            }
+        }
+
+        /**
+         * @return the response failure, or {@code null} if the response did not fail
+         */
+        public Throwable getFailure()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _failure;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s %s %d}", getClass().getSimpleName(), hashCode(), _method, _uri, _status);
+        }
+    }
+
+    /**
+     * <p>A {@link Sink} that records each response to its own file in a directory.</p>
+     * <p>Each file starts with the request line, the response status and content type,
+     * followed by an empty line and the recorded response content.</p>
+     */
+    public static class FileSink implements Sink
+    {
+        private final AtomicLong _counter = new AtomicLong();
+        private final Path _directory;
+
+        public FileSink(String directory)
+        {
+            this(Paths.get(directory));
+        }
+
+        public FileSink(Path directory)
+        {
+            _directory = Objects.requireNonNull(directory);
+        }
+
+        public Path getDirectory()
+        {
+            return _directory;
+        }
+
+        @Override
+        public Recording newRecording(Request request, Response response)
+        {
+            try
+            {
+                Files.createDirectories(_directory);
+                Path file = _directory.resolve(String.format("%d-%d.txt", System.currentTimeMillis(), _counter.incrementAndGet()));
+                FileChannel channel = FileChannel.open(file, StandardOpenOption.CREATE_NEW, StandardOpenOption.WRITE);
+                String head = String.format("%s %s %s\r\nStatus: %d\r\nContent-Type: %s\r\n\r\n",
+                    request.getMethod(), request.getRequestURI(), request.getProtocol(), response.getStatus(), response.getContentType());
+                FileRecording recording = new FileRecording(file, channel);
+                recording.writeFully(BufferUtil.toBuffer(head, StandardCharsets.UTF_8));
+                return recording;
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not record {}", request, x);
+                return null;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s}", getClass().getSimpleName(), hashCode(), _directory);
+        }
+    }
+
+    private static class FileRecording implements Recording
+    {
+        private final Path _file;
+        private final FileChannel _channel;
+
+        private FileRecording(Path file, FileChannel channel)
+        {
+            _file = file;
+            _channel = channel;
+        }
+
+        private void writeFully(ByteBuffer buffer) throws IOException
+        {
+            try
+            {
+                while (buffer.hasRemaining())
+                {
+                    _channel.write(buffer);
+                }
+            }
+            catch (IOException x)
+            {
+                _channel.close();
+                throw x;
+            }
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            try
+            {
+                writeFully(content);
+                if (last)
+                    _channel.close();
+                callback.succeeded();
+            }
+            catch (Throwable x)
+            {
+                callback.failed(x);
+            }
+        }
+
+        @Override
+        public void fail(Throwable failure)
+        {
+            try
+            {
+                _channel.close();
+            }
+            catch (IOException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Could not close {}", _file, x);
+            }
+        }
+    }
+
+    private class TeeInterceptor implements HttpOutput.Interceptor
+    {
+        private final Request _request;
+        private final Sink _sink;
+        private final HttpOutput.Interceptor _next;
+        private boolean _started;
+        private volatile Recording _recording;
+        private long _recorded;
+
+        private TeeInterceptor(Request request, Sink sink, HttpOutput.Interceptor next)
+        {
+            _request = request;
+            _sink = sink;
+            _next = next;
+        }
+
+        private ResponseTeeHandler getHandler()
+        {
+            return ResponseTeeHandler.this;
+        }
+
+        @Override
+        public void write(ByteBuffer content, boolean last, Callback callback)
+        {
+            if (!_started)
+            {
+                _started = true;
+                start();
+            }
+
+            Recording recording = _recording;
+            if (recording == null)
+            {
+                _next.write(content, last, callback);
+                return;
+            }
+
+            // The recording sees a read-only view of the
+            // application buffer, which is retained until
+            // both the client and the sink writes complete.
+            ByteBuffer chunk = content == null ? BufferUtil.EMPTY_BUFFER : content.asReadOnlyBuffer();
+            boolean lastChunk = last;
+            long max = getMaxContentLength();
+            if (max >= 0 && _recorded + chunk.remaining() >= max)
+            {
+                chunk.limit(chunk.position() + (int)(max - _recorded));
+                lastChunk = true;
+            }
+            _recorded += chunk.remaining();
+            if (lastChunk)
+                _recording = null;
+
+            if (!chunk.hasRemaining() && !lastChunk)
+            {
+                _next.write(content, last, new FailureCallback(recording, callback));
+                return;
+            }
+
+            CountingCallback counting = new CountingCallback(callback, 2);
+            recording.write(chunk, lastChunk, new SinkCallback(recording, counting));
+            _next.write(content, last, new FailureCallback(lastChunk ? null : recording, counting));
+        }
+
+        private void start()
+        {
+            Response response = _request.getResponse();
+            if (!isRecorded(_request, response))
+                return;
+            try
+            {
+                _recording = _sink.newRecording(_request, response);
+                if (_recording != null)
+                    _recordings.incrementAndGet();
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Could not record {}", _request, x);
+                _failures.incrementAndGet();
+            }
+        }
+
+        @Override
+        public HttpOutput.Interceptor getNextInterceptor()
+        {
+            return _next;
+        }
+
+        private class SinkCallback implements Callback
+        {
+            private final Recording _sinkRecording;
+            private final Callback _callback;
+
+            private SinkCallback(Recording recording, Callback callback)
+            {
+                _sinkRecording = recording;
+                _callback = callback;
+            }
+
+            @Override
+            public void succeeded()
+            {
+                _callback.succeeded();
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                // A sink failure must not fail the response.
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Abandoning recording {} of {}", _sinkRecording, _request, x);
+                if (_recording == _sinkRecording)
+                    _recording = null;
+                _failures.incrementAndGet();
+                _callback.succeeded();
+            }
+        }
+    }
+
+    private static class FailureCallback extends Callback.Nested
+    {
+        private final Recording _recording;
+
+        private FailureCallback(Recording recording, Callback callback)
+        {
+            super(callback);
+            _recording = recording;
+        }
+
+        @Override
+        public void failed(Throwable x)
+        {
+            if (_recording != null)
+                _recording.fail(x);
+            super.failed(x);
+        }
+    }
+}
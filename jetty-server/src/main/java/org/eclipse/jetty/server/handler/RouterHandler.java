@@ -0,0 +1,723 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.UUID;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.function.Function;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that dispatches requests to other handlers depending on the request method
+ * and on the request path, matched against URI templates.</p>
+ * <p>URI templates follow RFC 6570 levels 1 and 2, restricted to paths:</p>
+ * <ul>
+ * <li>{@code {name}} matches a non-empty part of a single path segment,
+ * for example {@code /users/{id}} or {@code /files/{name}.{ext}};</li>
+ * <li>{@code {+name}} matches the rest of the path, including {@code /} characters,
+ * and must be the last expression of the template, for example {@code /static{+path}}.</li>
+ * </ul>
+ * <p>An expression may declare the type of the path parameter after a colon,
+ * for example {@code /users/{id:int}}; the built-in types are {@code string} (the default),
+ * {@code int}, {@code long} and {@code uuid}, and more can be added via
+ * {@link #addParameterType(String, Function)}.
+ * A path segment whose value cannot be converted to the declared type does not match.</p>
+ * <p>Routes are compiled into a trie of path segments: literal segments are matched
+ * before segments with expressions, which are matched before {@code {+name}} expressions,
+ * so that {@code /users/me} takes precedence over {@code /users/{id}} regardless of the order
+ * in which the routes are added.</p>
+ * <p>When a route matches, the typed path parameters are set as request attributes,
+ * both individually under their name and as a {@code Map} under
+ * {@link #PATH_PARAMETERS_ATTRIBUTE}, and the request is handled by the route handler.
+ * When the path matches some routes, but none for the request method, a {@code 405}
+ * response with an {@code Allow} header is sent.
+ * When no route matches, the request is not handled, so that another handler may handle it.</p>
+ * <p>{@code HEAD} requests are routed to {@code GET} routes when there is no explicit {@code HEAD} route.
+ * The handling of trailing {@code /} characters is controlled by the {@link TrailingSlash} policy.</p>
+ */
+@ManagedObject("Router handler")
+public class RouterHandler extends AbstractHandlerContainer
+{
+    public static final String PATH_PARAMETERS_ATTRIBUTE = "org.eclipse.jetty.server.handler.RouterHandler.pathParameters";
+    private static final Logger LOG = LoggerFactory.getLogger(RouterHandler.class);
+    private static final String ANY_METHOD = "*";
+    private static final Pattern EXPRESSION = Pattern.compile("\\{(\\+?)[^}:]*(?::([^}]*))?}");
+
+    private final AutoLock _lock = new AutoLock();
+    private final Map<String, Function<String, Object>> _types = new ConcurrentHashMap<>();
+    private final List<Route> _routes = new CopyOnWriteArrayList<>();
+    private volatile Node _root = new Node();
+    private TrailingSlash _trailingSlash = TrailingSlash.STRICT;
+
+    public RouterHandler()
+    {
+        addParameterType("string", value -> value);
+        addParameterType("int", Integer::valueOf);
+        addParameterType("long", Long::valueOf);
+        addParameterType("uuid", UUID::fromString);
+    }
+
+    /**
+     * <p>Adds a path parameter type, that can be referenced by templates
+     * with the {@code {name:type}} syntax.</p>
+     * <p>The converter must throw an exception, or return {@code null},
+     * if the path parameter value is not valid for the type.</p>
+     *
+     * @param type the type name
+     * @param converter the function that converts path parameter values
+     */
+    public void addParameterType(String type, Function<String, Object> converter)
+    {
+        _types.put(Objects.requireNonNull(type), Objects.requireNonNull(converter));
+    }
+
+    /**
+     * @return the trailing slash policy
+     */
+    @ManagedAttribute("The trailing slash policy")
+    public TrailingSlash getTrailingSlash()
+    {
+        return _trailingSlash;
+    }
+
+    /**
+     * @param trailingSlash the trailing slash policy
+     */
+    public void setTrailingSlash(TrailingSlash trailingSlash)
+    {
+        _trailingSlash = Objects.requireNonNull(trailingSlash);
+    }
+
+    public Route get(String template, Handler handler)
+    {
+        return addRoute(HttpMethod.GET.asString(), template, handler);
+    }
+
+    public Route post(String template, Handler handler)
+    {
+        return addRoute(HttpMethod.POST.asString(), template, handler);
+    }
+
+    public Route put(String template, Handler handler)
+    {
+        return addRoute(HttpMethod.PUT.asString(), template, handler);
+    }
+
+    public Route delete(String template, Handler handler)
+    {
+        return addRoute(HttpMethod.DELETE.asString(), template, handler);
+    }
+
+    /**
+     * <p>Adds a route that matches any request method.</p>
+     *
+     * @param template the URI template
+     * @param handler the handler of the requests matching the route
+     * @return the route
+     */
+    public Route addRoute(String template, Handler handler)
+    {
+        return addRoute(null, template, handler);
+    }
+
+    /**
+     * <p>Adds a route.</p>
+     *
+     * @param method the request method, or {@code null} to match any request method
+     * @param template the URI template
+     * @param handler the handler of the requests matching the route
+     * @return the route
+     * @throws IllegalArgumentException if the template is invalid, or if a route
+     * with an equivalent template and the same method already exists
+     */
+    public Route addRoute(String method, String template, Handler handler)
+    {
+        Route route = new Route(method, template, Objects.requireNonNull(handler));
+        try (AutoLock l = _lock.lock())
+        {
+            List<Route> routes = new ArrayList<>(_routes);
+            routes.add(route);
+            _root = compile(routes);
+            _routes.add(route);
+        }
+        if (getServer() != null)
+            handler.setServer(getServer());
+        addBean(handler);
+        return route;
+    }
+
+    /**
+     * @param route the route to remove
+     * @return whether the route was removed
+     */
+    public boolean removeRoute(Route route)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (!_routes.remove(route))
+                return false;
+            _root = compile(_routes);
+        }
+        if (_routes.stream().noneMatch(r -> r.getHandler() == route.getHandler()))
+            removeBean(route.getHandler());
+        return true;
+    }
+
+    /**
+     * @return the routes, in the order they have been added
+     */
+    public List<Route> getRoutes()
+    {
+        return Collections.unmodifiableList(_routes);
+    }
+
+    @Override
+    public Handler[] getHandlers()
+    {
+        return _routes.stream().map(Route::getHandler).distinct().toArray(Handler[]::new);
+    }
+
+    @Override
+    protected void expandChildren(List<Handler> list, Class<?> byClass)
+    {
+        for (Handler handler : getHandlers())
+        {
+            expandHandler(handler, list, byClass);
+        }
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.isHandled() || !isStarted())
+            return;
+
+        String method = request.getMethod();
+        Match match = match(method, target);
+        if ((match == null || match.getRoute() == null) && getTrailingSlash() != TrailingSlash.STRICT)
+        {
+            String alternate = target.length() > 1 && target.endsWith("/") ? target.substring(0, target.length() - 1) : target + "/";
+            Match alternateMatch = match(method, alternate);
+            if (alternateMatch != null && alternateMatch.getRoute() != null)
+            {
+                if (getTrailingSlash() == TrailingSlash.REDIRECT)
+                {
+                    redirect(baseRequest, target, alternate);
+                    return;
+                }
+                match = alternateMatch;
+            }
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} {} matched {}", method, target, match);
+
+        if (match == null)
+            return;
+
+        if (match.getRoute() == null)
+        {
+            baseRequest.setHandled(true);
+            response.setHeader(HttpHeader.ALLOW.asString(), String.join(", ", match.getAllowedMethods()));
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+
+        Map<String, Object> parameters = match.getParameters();
+        parameters.forEach(baseRequest::setAttribute);
+        baseRequest.setAttribute(PATH_PARAMETERS_ATTRIBUTE, parameters);
+        match.getRoute().getHandler().handle(target, baseRequest, request, response);
+    }
+
+    private void redirect(Request baseRequest, String target, String alternate) throws IOException
+    {
+        String uri = baseRequest.getRequestURI();
+        // Only toggle the trailing slash of the request URI, which may be encoded.
+        String location = alternate.endsWith("/") ? uri + "/" : uri.substring(0, uri.length() - 1);
+        String query = baseRequest.getQueryString();
+        if (query != null)
+            location += "?" + query;
+        String method = baseRequest.getMethod();
+        int code = HttpMethod.GET.is(method) || HttpMethod.HEAD.is(method) ? HttpStatus.MOVED_PERMANENTLY_301 : HttpStatus.PERMANENT_REDIRECT_308;
+        baseRequest.getResponse().sendRedirect(code, location, true);
+    }
+
+    /**
+     * <p>Matches the given request method and path against the routes.</p>
+     *
+     * @param method the request method
+     * @param path the request path, relative to the context
+     * @return the match, or {@code null} if the path does not match any route;
+     * if the path matches some routes but none for the method, the match has a
+     * {@code null} {@link Match#getRoute() route} and non-empty {@link Match#getAllowedMethods() allowed methods}
+     */
+    public Match match(String method, String path)
+    {
+        if (path == null || !path.startsWith("/"))
+            return null;
+        String[] segments = path.substring(1).split("/", -1);
+        Set<String> allowed = new LinkedHashSet<>();
+        Map<String, Object> parameters = new LinkedHashMap<>();
+        Route route = _root.find(method, segments, 0, parameters, allowed);
+        if (route != null)
+            return new Match(route, parameters, null);
+        if (allowed.isEmpty())
+            return null;
+        return new Match(null, Collections.emptyMap(), allowed);
+    }
+
+    private Node compile(List<Route> routes)
+    {
+        Node root = new Node();
+        Set<String> keys = new HashSet<>();
+        for (Route route : routes)
+        {
+            // Templates that only differ by variable names are equivalent.
+            String method = route.getMethod() == null ? ANY_METHOD : route.getMethod();
+            Matcher matcher = EXPRESSION.matcher(route.getTemplate());
+            StringBuilder key = new StringBuilder(method).append(' ');
+            while (matcher.find())
+            {
+                String type = matcher.group(2) == null ? "string" : matcher.group(2);
+                matcher.appendReplacement(key, Matcher.quoteReplacement("{" + matcher.group(1) + ":" + type + "}"));
+            }
+            matcher.appendTail(key);
+            if (!keys.add(key.toString()))
+                throw new IllegalArgumentException("Duplicate route " + route);
+
+            Node node = root;
+            List<String> segments = route.getSegments();
+            for (int i = 0; i < segments.size(); ++i)
+            {
+                String segment = segments.get(i);
+                int reserved = segment.indexOf("{+");
+                if (reserved >= 0)
+                {
+                    if (i != segments.size() - 1 || !segment.endsWith("}") || segment.indexOf('{', reserved + 1) >= 0)
+                        throw new IllegalArgumentException("Expression {+name} must be the last of template " + route.getTemplate());
+                    String prefix = segment.substring(0, reserved);
+                    if (prefix.indexOf('{') >= 0)
+                        throw new IllegalArgumentException("Expression {+name} must be the last of template " + route.getTemplate());
+                    Variable variable = newVariable(segment.substring(reserved + 2, segment.length() - 1), route);
+                    node = node.tail(prefix, variable);
+                }
+                else if (segment.indexOf('{') >= 0 || segment.indexOf('}') >= 0)
+                {
+                    node = node.pattern(newSegmentPattern(segment, route));
+                }
+                else
+                {
+                    node = node._literals.computeIfAbsent(segment, k -> new Node());
+                }
+            }
+            node._routes.put(method, route);
+        }
+        return root;
+    }
+
+    private SegmentPattern newSegmentPattern(String segment, Route route)
+    {
+        StringBuilder regex = new StringBuilder();
+        List<Variable> variables = new ArrayList<>();
+        int index = 0;
+        while (index < segment.length())
+        {
+            int open = segment.indexOf('{', index);
+            int close = segment.indexOf('}', index);
+            if (open < 0)
+            {
+                if (close >= 0)
+                    throw new IllegalArgumentException("Invalid template " + route.getTemplate());
+                regex.append(Pattern.quote(segment.substring(index)));
+                break;
+            }
+            if (close < open)
+                throw new IllegalArgumentException("Invalid template " + route.getTemplate());
+            if (open > index)
+                regex.append(Pattern.quote(segment.substring(index, open)));
+            variables.add(newVariable(segment.substring(open + 1, close), route));
+            regex.append("([^/]+?)");
+            index = close + 1;
+        }
+        return new SegmentPattern(segment, Pattern.compile(regex.toString()), variables);
+    }
+
+    private Variable newVariable(String expression, Route route)
+    {
+        if (expression.isEmpty())
+            throw new IllegalArgumentException("Empty expression in template " + route.getTemplate());
+        char operator = expression.charAt(0);
+        if ("#./;?&=,!@|".indexOf(operator) >= 0)
+            throw new IllegalArgumentException("Unsupported expression operator '" + operator + "' in template " + route.getTemplate());
+        if (expression.indexOf(',') >= 0 || expression.indexOf('*') >= 0)
+            throw new IllegalArgumentException("Unsupported multiple variables in template " + route.getTemplate());
+        String name = expression;
+        String type = "string";
+        int colon = expression.indexOf(':');
+        if (colon >= 0)
+        {
+            name = expression.substring(0, colon);
+            type = expression.substring(colon + 1);
+        }
+        if (name.isEmpty() || !name.matches("[A-Za-z0-9_.%]+"))
+            throw new IllegalArgumentException("Invalid variable name '" + name + "' in template " + route.getTemplate());
+        Function<String, Object> converter = _types.get(type);
+        if (converter == null)
+            throw new IllegalArgumentException("Unknown type '" + type + "' in template " + route.getTemplate());
+        return new Variable(name, type, converter);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,routes=%d}", getClass().getSimpleName(), hashCode(), getState(), _routes.size());
+    }
+
+    /**
+     * <p>The handling of trailing {@code /} characters in request paths.</p>
+     */
+    public enum TrailingSlash
+    {
+        /**
+         * A trailing {@code /} must match the template exactly.
+         */
+        STRICT,
+        /**
+         * If the path does not match, the path with the trailing {@code /} added
+         * or removed is used instead.
+         */
+        IGNORE,
+        /**
+         * If the path does not match, but the path with the trailing {@code /} added
+         * or removed does, the client is redirected to the latter.
+         */
+        REDIRECT
+    }
+
+    /**
+     * <p>A route, mapping a request method and a URI template to a handler.</p>
+     */
+    public static class Route
+    {
+        private final String _method;
+        private final String _template;
+        private final Handler _handler;
+        private final List<String> _segments;
+
+        private Route(String method, String template, Handler handler)
+        {
+            if (template == null || !template.startsWith("/"))
+                throw new IllegalArgumentException("Template must start with '/': " + template);
+            _method = method;
+            _template = template;
+            _handler = handler;
+            List<String> segments = new ArrayList<>();
+            // Split on '/' characters that are not within expressions.
+            int depth = 0;
+            int start = 1;
+            for (int i = 1; i < template.length(); ++i)
+            {
+                char c = template.charAt(i);
+                if (c == '{')
+                    ++depth;
+                else if (c == '}')
+                    --depth;
+                else if (c == '/' && depth == 0)
+                {
+                    segments.add(template.substring(start, i));
+                    start = i + 1;
+                }
+            }
+            segments.add(template.substring(start));
+            _segments = List.copyOf(segments);
+        }
+
+        /**
+         * @return the request method, or {@code null} if the route matches any request method
+         */
+        public String getMethod()
+        {
+            return _method;
+        }
+
+        public String getTemplate()
+        {
+            return _template;
+        }
+
+        public Handler getHandler()
+        {
+            return _handler;
+        }
+
+        private List<String> getSegments()
+        {
+            return _segments;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s %s -> %s", _method == null ? ANY_METHOD : _method, _template, _handler);
+        }
+    }
+
+    /**
+     * <p>The result of {@link #match(String, String) matching} a request against the routes.</p>
+     */
+    public static class Match
+    {
+        private final Route _route;
+        private final Map<String, Object> _parameters;
+        private final Set<String> _allowedMethods;
+
+        private Match(Route route, Map<String, Object> parameters, Set<String> allowedMethods)
+        {
+            _route = route;
+            _parameters = Collections.unmodifiableMap(parameters);
+            _allowedMethods = allowedMethods == null ? Set.of() : Collections.unmodifiableSet(allowedMethods);
+        }
+
+        /**
+         * @return the matched route, or {@code null} if no route matches the request method
+         */
+        public Route getRoute()
+        {
+            return _route;
+        }
+
+        /**
+         * @return the typed path parameters
+         */
+        public Map<String, Object> getParameters()
+        {
+            return _parameters;
+        }
+
+        /**
+         * @return the methods of the routes matching the path, when no route matches the request method
+         */
+        public Set<String> getAllowedMethods()
+        {
+            return _allowedMethods;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[route=%s,parameters=%s,allowed=%s]", getClass().getSimpleName(), hashCode(), _route, _parameters, _allowedMethods);
+        }
+    }
+
+    private static class Variable
+    {
+        private final String _name;
+        private final String _type;
+        private final Function<String, Object> _converter;
+
+        private Variable(String name, String type, Function<String, Object> converter)
+        {
+            _name = name;
+            _type = type;
+            _converter = converter;
+        }
+
+        private Object convert(String value)
+        {
+            try
+            {
+                return _converter.apply(value);
+            }
+            catch (RuntimeException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Invalid {} value '{}' for {}", _type, value, _name, x);
+                return null;
+            }
+        }
+    }
+
+    private static class SegmentPattern
+    {
+        private final String _segment;
+        private final Pattern _pattern;
+        private final List<Variable> _variables;
+        private final Node _node = new Node();
+
+        private SegmentPattern(String segment, Pattern pattern, List<Variable> variables)
+        {
+            _segment = segment;
+            _pattern = pattern;
+            _variables = variables;
+        }
+
+        private boolean match(String segment, Map<String, Object> parameters)
+        {
+            Matcher matcher = _pattern.matcher(segment);
+            if (!matcher.matches())
+                return false;
+            for (int i = 0; i < _variables.size(); ++i)
+            {
+                Variable variable = _variables.get(i);
+                Object value = variable.convert(matcher.group(i + 1));
+                if (value == null)
+                    return false;
+                parameters.put(variable._name, value);
+            }
+            return true;
+        }
+    }
+
+    private static class Tail
+    {
+        private final String _prefix;
+        private final Variable _variable;
+        private final Node _node = new Node();
+
+        private Tail(String prefix, Variable variable)
+        {
+            _prefix = prefix;
+            _variable = variable;
+        }
+    }
+
+    private static class Node
+    {
+        private final Map<String, Node> _literals = new HashMap<>();
+        private final List<SegmentPattern> _patterns = new ArrayList<>();
+        private final List<Tail> _tails = new ArrayList<>();
+        private final Map<String, Route> _routes = new LinkedHashMap<>();
+
+        private Node pattern(SegmentPattern pattern)
+        {
+            for (SegmentPattern existing : _patterns)
+            {
+                if (existing._segment.equals(pattern._segment))
+                    return existing._node;
+            }
+            _patterns.add(pattern);
+            return pattern._node;
+        }
+
+        private Node tail(String prefix, Variable variable)
+        {
+            for (Tail existing : _tails)
+            {
+                if (existing._prefix.equals(prefix) && existing._variable._name.equals(variable._name) && existing._variable._type.equals(variable._type))
+                    return existing._node;
+            }
+            Tail tail = new Tail(prefix, variable);
+            _tails.add(tail);
+            return tail._node;
+        }
+
+        private Route find(String method, String[] segments, int index, Map<String, Object> parameters, Set<String> allowed)
+        {
+            if (index == segments.length)
+                return route(method, allowed);
+
+            String segment = segments[index];
+            Node literal = _literals.get(segment);
+            if (literal != null)
+            {
+                Route route = literal.find(method, segments, index + 1, parameters, allowed);
+                if (route != null)
+                    return route;
+            }
+
+            for (SegmentPattern pattern : _patterns)
+            {
+                Map<String, Object> values = new LinkedHashMap<>();
+                if (pattern.match(segment, values))
+                {
+                    Route route = pattern._node.find(method, segments, index + 1, values, allowed);
+                    if (route != null)
+                    {
+                        parameters.putAll(values);
+                        return route;
+                    }
+                }
+            }
+
+            if (!_tails.isEmpty())
+            {
+                String rest = String.join("/", List.of(segments).subList(index, segments.length));
+                for (Tail tail : _tails)
+                {
+                    if (rest.length() > tail._prefix.length() && rest.startsWith(tail._prefix))
+                    {
+                        Object value = tail._variable.convert(rest.substring(tail._prefix.length()));
+                        if (value == null)
+                            continue;
+                        Route route = tail._node.route(method, allowed);
+                        if (route != null)
+                        {
+                            parameters.put(tail._variable._name, value);
+                            return route;
+                        }
+                    }
+                }
+            }
+            return null;
+        }
+
+        private Route route(String method, Set<String> allowed)
+        {
+            if (_routes.isEmpty())
+                return null;
+            Route route = _routes.get(method);
+            if (route == null && HttpMethod.HEAD.is(method))
+                route = _routes.get(HttpMethod.GET.asString());
+            if (route == null)
+                route = _routes.get(ANY_METHOD);
+            if (route == null)
+            {
+                for (String routeMethod : _routes.keySet())
+                {
+                    allowed.add(routeMethod);
+                    if (HttpMethod.GET.is(routeMethod))
+                        allowed.add(HttpMethod.HEAD.asString());
+                }
+            }
+            return route;
+        }
+    }
+}
@@ -18,6 +18,7 @@ import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.server.HandlerInstrumentation;
 import org.eclipse.jetty.server.HttpChannel;
 import org.eclipse.jetty.server.HttpConnection;
 import org.eclipse.jetty.server.Request;
@@ -171,9 +172,9 @@ public abstract class ScopedHandler extends HandlerWrapper
         throws IOException, ServletException
     {
         if (_nextScope != null)
-            _nextScope.doScope(target, baseRequest, request, response);
+            invoke(_nextScope, true, target, baseRequest, request, response);
         else if (_outerScope != null)
-            _outerScope.doHandle(target, baseRequest, request, response);
+            invoke(_outerScope, false, target, baseRequest, request, response);
         else
             doHandle(target, baseRequest, request, response);
     }
@@ -218,8 +219,32 @@ public abstract class ScopedHandler extends HandlerWrapper
     public final void nextHandle(String target, final Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
     {
         if (_nextScope != null && _nextScope == _handler)
-            _nextScope.doHandle(target, baseRequest, request, response);
+            invoke(_nextScope, false, target, baseRequest, request, response);
         else if (_handler != null)
             super.handle(target, baseRequest, request, response);
     }
+
+    private static void invoke(ScopedHandler handler, boolean scope, String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HandlerInstrumentation instrumentation = HandlerInstrumentation.from(baseRequest);
+        Object context = instrumentation == null ? null : instrumentation.onHandleBegin(handler, baseRequest);
+        Throwable failure = null;
+        try
+        {
+            if (scope)
+                handler.doScope(target, baseRequest, request, response);
+            else
+                handler.doHandle(target, baseRequest, request, response);
+        }
+        catch (Throwable x)
+        {
+            failure = x;
+            throw x;
+        }
+        finally
+        {
+            if (instrumentation != null)
+                instrumentation.onHandleEnd(handler, baseRequest, context, failure);
+        }
+    }
 }
@@ -14,11 +14,20 @@
 package org.eclipse.jetty.server.handler;
 
 import java.io.IOException;
+import java.util.Locale;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArraySet;
+import java.util.concurrent.TimeUnit;
 import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
 import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.PreEncodedHttpField;
+import org.eclipse.jetty.http.pathmap.PathSpecSet;
 import org.eclipse.jetty.server.HttpChannel;
 import org.eclipse.jetty.server.HttpConfiguration;
 import org.eclipse.jetty.server.Request;
@@ -30,13 +39,37 @@ import org.eclipse.jetty.util.URIUtil;
  * attempting to redirect to the {@link HttpConfiguration#getSecureScheme()} and
  * {@link HttpConfiguration#getSecurePort()} for any request that
  * {@link HttpServletRequest#isSecure()} is false.</p>
+ * <p>Requests for {@link #addExcludedHost(String) excluded hosts} or
+ * {@link #addExcludedPath(String) excluded paths}, for example health checks
+ * or ACME challenges, are not redirected and are handled normally.</p>
+ * <p>Non-idempotent requests, such as {@code POST}, would be turned into
+ * {@code GET} requests by most clients following a redirect, so they can
+ * optionally be {@link #setRejectNonIdempotentMethods(boolean) rejected}
+ * with a 403 response instead.</p>
+ * <p>Secure responses may carry a {@code Strict-Transport-Security} header,
+ * configured with {@link #setHstsMaxAge(long)}, {@link #setHstsIncludeSubDomains(boolean)}
+ * and {@link #setHstsPreload(boolean)}, unless the host is excluded.
+ * The HSTS preload lists require a max age of at least one year and
+ * {@code includeSubDomains}, so these are verified when preload is enabled.</p>
  */
 public class SecuredRedirectHandler extends HandlerWrapper
 {
+    /**
+     * The minimum HSTS max age in seconds required for preloading.
+     */
+    public static final long HSTS_PRELOAD_MIN_MAX_AGE = TimeUnit.DAYS.toSeconds(365);
+
     /**
      * The redirect code to send in response.
      */
     private final int _redirectCode;
+    private final Set<String> _excludedHosts = new CopyOnWriteArraySet<>();
+    private final PathSpecSet _excludedPaths = new PathSpecSet();
+    private boolean _rejectNonIdempotentMethods;
+    private long _hstsMaxAge = -1;
+    private boolean _hstsIncludeSubDomains;
+    private boolean _hstsPreload;
+    private HttpField _hstsField;
 
     /**
      * Uses moved temporarily code (302) as the redirect code.
@@ -59,13 +92,170 @@ public class SecuredRedirectHandler extends HandlerWrapper
         _redirectCode = code;
     }
 
+    /**
+     * @return the hosts that are not redirected
+     */
+    public Set<String> getExcludedHosts()
+    {
+        return Set.copyOf(_excludedHosts);
+    }
+
+    /**
+     * <p>Adds a host that is not redirected, and for which no HSTS header is sent.</p>
+     *
+     * @param host the host name, or a wildcard such as {@code *.example.com}
+     * to exclude all the sub domains of a domain
+     */
+    public void addExcludedHost(String host)
+    {
+        _excludedHosts.add(host.toLowerCase(Locale.ENGLISH));
+    }
+
+    /**
+     * @param hosts the hosts that are not redirected
+     * @see #addExcludedHost(String)
+     */
+    public void addExcludedHosts(String... hosts)
+    {
+        for (String host : hosts)
+        {
+            addExcludedHost(host);
+        }
+    }
+
+    /**
+     * @return the path specs of the requests that are not redirected
+     */
+    public Set<String> getExcludedPaths()
+    {
+        return Set.copyOf(_excludedPaths);
+    }
+
+    /**
+     * <p>Adds a path spec of the requests that are not redirected,
+     * for example {@code /.well-known/acme-challenge/*}.</p>
+     *
+     * @param pathSpec the path spec
+     */
+    public void addExcludedPath(String pathSpec)
+    {
+        _excludedPaths.add(pathSpec);
+    }
+
+    /**
+     * @param pathSpecs the path specs of the requests that are not redirected
+     * @see #addExcludedPath(String)
+     */
+    public void addExcludedPaths(String... pathSpecs)
+    {
+        for (String pathSpec : pathSpecs)
+        {
+            addExcludedPath(pathSpec);
+        }
+    }
+
+    /**
+     * @return whether requests with non-idempotent methods are rejected with 403 instead of redirected
+     */
+    public boolean isRejectNonIdempotentMethods()
+    {
+        return _rejectNonIdempotentMethods;
+    }
+
+    /**
+     * @param reject whether requests with non-idempotent methods are rejected with 403 instead of redirected
+     */
+    public void setRejectNonIdempotentMethods(boolean reject)
+    {
+        _rejectNonIdempotentMethods = reject;
+    }
+
+    /**
+     * @return the max age in seconds of the Strict-Transport-Security header, or -1 if no header is sent
+     */
+    public long getHstsMaxAge()
+    {
+        return _hstsMaxAge;
+    }
+
+    /**
+     * @param maxAge the max age in seconds of the Strict-Transport-Security header sent
+     * in secure responses, or -1 if no header is sent
+     */
+    public void setHstsMaxAge(long maxAge)
+    {
+        _hstsMaxAge = maxAge;
+        formatHsts();
+    }
+
+    /**
+     * @return whether the Strict-Transport-Security header has the {@code includeSubDomains} directive
+     */
+    public boolean isHstsIncludeSubDomains()
+    {
+        return _hstsIncludeSubDomains;
+    }
+
+    /**
+     * @param includeSubDomains whether the Strict-Transport-Security header has the {@code includeSubDomains} directive
+     */
+    public void setHstsIncludeSubDomains(boolean includeSubDomains)
+    {
+        _hstsIncludeSubDomains = includeSubDomains;
+        formatHsts();
+    }
+
+    /**
+     * @return whether the Strict-Transport-Security header has the {@code preload} directive
+     */
+    public boolean isHstsPreload()
+    {
+        return _hstsPreload;
+    }
+
+    /**
+     * @param preload whether the Strict-Transport-Security header has the {@code preload} directive
+     */
+    public void setHstsPreload(boolean preload)
+    {
+        _hstsPreload = preload;
+        formatHsts();
+    }
+
+    private void formatHsts()
+    {
+        if (_hstsMaxAge < 0)
+            _hstsField = null;
+        else
+            _hstsField = new PreEncodedHttpField(HttpHeader.STRICT_TRANSPORT_SECURITY, String.format("max-age=%d%s%s",
+                _hstsMaxAge, _hstsIncludeSubDomains ? "; includeSubDomains" : "", _hstsPreload ? "; preload" : ""));
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_hstsPreload && (_hstsMaxAge < HSTS_PRELOAD_MIN_MAX_AGE || !_hstsIncludeSubDomains))
+            throw new IllegalStateException("HSTS preload requires includeSubDomains and a max age of at least " + HSTS_PRELOAD_MIN_MAX_AGE + " seconds");
+        super.doStart();
+    }
+
     @Override
     public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
     {
         HttpChannel channel = baseRequest.getHttpChannel();
         if (baseRequest.isSecure() || channel == null)
         {
-            // Nothing to do here.
+            // Nothing to do here, except adding the HSTS header.
+            HttpField hstsField = _hstsField;
+            if (hstsField != null && channel != null && !isExcludedHost(baseRequest.getServerName()))
+                baseRequest.getResponse().getHttpFields().put(hstsField);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        if (isExcludedHost(baseRequest.getServerName()) || _excludedPaths.test(target))
+        {
+            // Excluded requests are handled over plaintext.
             super.handle(target, baseRequest, request, response);
             return;
         }
@@ -79,6 +269,16 @@ public class SecuredRedirectHandler extends HandlerWrapper
             return;
         }
 
+        if (_rejectNonIdempotentMethods)
+        {
+            HttpMethod method = HttpMethod.fromString(baseRequest.getMethod());
+            if (method == null || !method.isIdempotent())
+            {
+                response.sendError(HttpStatus.FORBIDDEN_403, "Insecure " + baseRequest.getMethod() + " request");
+                return;
+            }
+        }
+
         int securePort = httpConfig.getSecurePort();
         if (securePort > 0)
         {
@@ -92,4 +292,19 @@ public class SecuredRedirectHandler extends HandlerWrapper
             response.sendError(HttpStatus.FORBIDDEN_403, "HttpConfiguration.securePort not configured");
         }
     }
+
+    private boolean isExcludedHost(String host)
+    {
+        if (_excludedHosts.isEmpty() || host == null)
+            return false;
+        host = host.toLowerCase(Locale.ENGLISH);
+        if (_excludedHosts.contains(host))
+            return true;
+        for (int dot = host.indexOf('.'); dot >= 0; dot = host.indexOf('.', dot + 1))
+        {
+            if (_excludedHosts.contains("*" + host.substring(dot)))
+                return true;
+        }
+        return false;
+    }
 }
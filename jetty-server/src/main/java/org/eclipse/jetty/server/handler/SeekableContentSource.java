@@ -0,0 +1,66 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.io.OutputStream;
+
+/**
+ * <p>Content of known length that can be written starting from any offset,
+ * typically generated on demand or read from a blob store.</p>
+ *
+ * @see RangeSupportHandler
+ */
+public interface SeekableContentSource
+{
+    /**
+     * @return the length in bytes of the whole content
+     */
+    long getLength();
+
+    /**
+     * @return the content type, or {@code null} to leave the response content type unchanged
+     */
+    default String getContentType()
+    {
+        return null;
+    }
+
+    /**
+     * @return the entity tag of the content, including quotes, such as {@code "abc"} or
+     * {@code W/"abc"}, or {@code null} if the content has no entity tag
+     */
+    default String getETag()
+    {
+        return null;
+    }
+
+    /**
+     * @return the last modified time of the content in milliseconds since the epoch, or -1 if unknown
+     */
+    default long getLastModified()
+    {
+        return -1;
+    }
+
+    /**
+     * <p>Writes a portion of the content to the given stream.</p>
+     *
+     * @param output the stream to write to
+     * @param offset the offset of the first byte to write
+     * @param length the number of bytes to write
+     * @throws IOException if the content cannot be written
+     */
+    void writeTo(OutputStream output, long offset, long length) throws IOException;
+}
@@ -0,0 +1,616 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.BufferedReader;
+import java.io.IOException;
+import java.io.InputStreamReader;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.Enumeration;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.TreeMap;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.CopyOnWriteArrayList;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletRequestWrapper;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.pathmap.MappedResource;
+import org.eclipse.jetty.http.pathmap.PathMappings;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.URIUtil;
+import org.eclipse.jetty.util.resource.Resource;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link ResourceHandler} that serves a complete static site, without a servlet context.</p>
+ * <p>In addition to the features of {@link ResourceHandler}, this handler supports:</p>
+ * <ul>
+ * <li>a redirects file in the base resource, by default {@code /_redirects}, and
+ * {@link #addRule(String, String, int, boolean) rules} added programmatically, see {@link Rule};</li>
+ * <li>{@link #addAlias(String, Resource) directory aliases}, that serve the paths
+ * under a prefix from a different directory;</li>
+ * <li>clean URLs, that serve {@code /foo.html} for {@code /foo};</li>
+ * <li>{@link #setErrorPage(int, String) error pages} from the base resource,
+ * by default {@code /404.html} when no resource is found, and {@code /500.html}
+ * when serving a resource fails;</li>
+ * <li>{@code Cache-Control} values {@link #addCacheControl(String, String) per path spec}.</li>
+ * </ul>
+ * <p>Requests for which no resource nor error page is found are passed to the wrapped
+ * handler, if any, like {@link ResourceHandler} does.</p>
+ */
+public class StaticSiteHandler extends ResourceHandler
+{
+    private static final Logger LOG = LoggerFactory.getLogger(StaticSiteHandler.class);
+
+    private final List<Rule> _rules = new CopyOnWriteArrayList<>();
+    private final List<Rule> _fileRules = new CopyOnWriteArrayList<>();
+    private final Map<String, Resource> _aliases = new ConcurrentHashMap<>();
+    private final Map<Integer, String> _errorPages = new ConcurrentHashMap<>();
+    private final PathMappings<String> _cacheControls = new PathMappings<>();
+    private String _redirectsFile = "/_redirects";
+    private boolean _cleanUrls = true;
+
+    public StaticSiteHandler()
+    {
+        setErrorPage(HttpStatus.NOT_FOUND_404, "/404.html");
+        setErrorPage(HttpStatus.INTERNAL_SERVER_ERROR_500, "/500.html");
+    }
+
+    /**
+     * @return the path of the redirects file in the base resource, or null if no file is read
+     */
+    public String getRedirectsFile()
+    {
+        return _redirectsFile;
+    }
+
+    /**
+     * <p>Sets the path of the redirects file, that is read when this handler is started.</p>
+     * <p>The redirects file itself is never served.</p>
+     *
+     * @param redirectsFile the path of the redirects file in the base resource, or null to read no file
+     * @see Rule#parse(String)
+     */
+    public void setRedirectsFile(String redirectsFile)
+    {
+        _redirectsFile = redirectsFile;
+    }
+
+    /**
+     * @return whether {@code /foo.html} is served for {@code /foo}
+     */
+    public boolean isCleanUrls()
+    {
+        return _cleanUrls;
+    }
+
+    /**
+     * @param cleanUrls whether {@code /foo.html} is served for {@code /foo}
+     */
+    public void setCleanUrls(boolean cleanUrls)
+    {
+        _cleanUrls = cleanUrls;
+    }
+
+    /**
+     * @param status the response status
+     * @return the path of the error page in the base resource, or null if there is no error page
+     */
+    public String getErrorPage(int status)
+    {
+        return _errorPages.get(status);
+    }
+
+    /**
+     * <p>Sets the error page for the given status, that is served if it exists.</p>
+     * <p>The {@code 404} page is served when no resource is found, and the
+     * {@code 500} page when serving a resource fails.
+     * Other error pages may be the target of {@link Rule}s.</p>
+     *
+     * @param status the response status
+     * @param path the path of the error page in the base resource, or null to remove the error page
+     */
+    public void setErrorPage(int status, String path)
+    {
+        if (path == null)
+            _errorPages.remove(status);
+        else
+            _errorPages.put(status, path);
+    }
+
+    /**
+     * @param pathSpec the path spec, for example {@code /assets/*} or {@code *.html}
+     * @param cacheControl the {@code Cache-Control} value of the resources matching the path spec
+     */
+    public void addCacheControl(String pathSpec, String cacheControl)
+    {
+        _cacheControls.put(pathSpec, Objects.requireNonNull(cacheControl));
+    }
+
+    /**
+     * @param path the path of a resource
+     * @return the {@code Cache-Control} value of the resource, or null if no path spec matches
+     */
+    public String getCacheControl(String path)
+    {
+        MappedResource<String> match = _cacheControls.getMatch(path);
+        return match == null ? null : match.getResource();
+    }
+
+    /**
+     * <p>Serves the paths under the given prefix from the given directory,
+     * for example {@code /docs/} from a directory outside of the base resource.</p>
+     *
+     * @param prefix the path prefix, for example {@code /docs}
+     * @param directory the directory serving the paths under the prefix
+     */
+    public void addAlias(String prefix, Resource directory)
+    {
+        _aliases.put(URIUtil.canonicalPath(prefix.endsWith("/") ? prefix : prefix + "/"), Objects.requireNonNull(directory));
+    }
+
+    /**
+     * @return the directory aliases, by path prefix
+     */
+    public Map<String, Resource> getAliases()
+    {
+        return new TreeMap<>(_aliases);
+    }
+
+    /**
+     * @param from the path pattern, see {@link Rule}
+     * @param to the target, see {@link Rule}
+     * @param status the status, either a redirect status, {@code 200} for a rewrite, or an error status
+     * @param force whether the rule applies even if a resource exists for the path
+     */
+    public void addRule(String from, String to, int status, boolean force)
+    {
+        _rules.add(new Rule(from, to, status, force));
+    }
+
+    /**
+     * @return the rules added programmatically, followed by those read from the redirects file
+     */
+    public List<Rule> getRules()
+    {
+        List<Rule> rules = new ArrayList<>(_rules);
+        rules.addAll(_fileRules);
+        return rules;
+    }
+
+    @Override
+    public void doStart() throws Exception
+    {
+        super.doStart();
+        _fileRules.clear();
+        String redirectsFile = getRedirectsFile();
+        if (redirectsFile != null)
+        {
+            Resource resource = getResource(redirectsFile);
+            if (resource.exists() && !resource.isDirectory())
+            {
+                try (BufferedReader reader = new BufferedReader(new InputStreamReader(resource.getInputStream(), StandardCharsets.UTF_8)))
+                {
+                    String line;
+                    int number = 0;
+                    while ((line = reader.readLine()) != null)
+                    {
+                        ++number;
+                        try
+                        {
+                            Rule rule = Rule.parse(line);
+                            if (rule != null)
+                                _fileRules.add(rule);
+                        }
+                        catch (IllegalArgumentException x)
+                        {
+                            LOG.warn("Invalid rule at {}:{}: {}", resource, number, line, x);
+                        }
+                    }
+                }
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Read {} rules from {}", _fileRules.size(), resource);
+            }
+        }
+    }
+
+    @Override
+    public Resource getResource(String path) throws IOException
+    {
+        // The longest matching prefix wins.
+        String match = null;
+        for (String prefix : _aliases.keySet())
+        {
+            if ((path.startsWith(prefix) || path.equals(prefix.substring(0, prefix.length() - 1))) &&
+                (match == null || prefix.length() > match.length()))
+                match = prefix;
+        }
+        if (match == null)
+            return super.getResource(path);
+
+        String remainder = path.length() > match.length() ? path.substring(match.length() - 1) : "/";
+        Resource resource = _aliases.get(match).addPath(remainder);
+        if (resource.isAlias() && (_context == null || !_context.checkAlias(path, resource)))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Rejected alias resource={} alias={}", resource, resource.getAlias());
+            throw new IllegalStateException("Rejected alias reference: " + path);
+        }
+        return resource;
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.isHandled())
+            return;
+
+        if (!HttpMethod.GET.is(request.getMethod()) && !HttpMethod.HEAD.is(request.getMethod()))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        String path = URIUtil.addPaths(request.getServletPath(), request.getPathInfo());
+        if (path == null)
+            path = "/";
+
+        try
+        {
+            if (serveSite(path, baseRequest, request, response))
+                return;
+        }
+        catch (IOException | ServletException | RuntimeException x)
+        {
+            if (response.isCommitted() || !serveErrorPage(HttpStatus.INTERNAL_SERVER_ERROR_500, baseRequest, request, response))
+                throw x;
+            LOG.warn("Failed to serve {}", path, x);
+            return;
+        }
+
+        Handler handler = getHandler();
+        if (handler != null)
+            handler.handle(target, baseRequest, request, response);
+        if (!baseRequest.isHandled())
+            serveErrorPage(HttpStatus.NOT_FOUND_404, baseRequest, request, response);
+    }
+
+    private boolean serveSite(String path, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        String cacheControl = getCacheControl(path);
+        if (cacheControl != null)
+            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), cacheControl);
+
+        boolean hidden = path.equals(getRedirectsFile());
+        for (Rule rule : getRules())
+        {
+            String to = rule.apply(path);
+            if (to == null)
+                continue;
+            if (!rule.isForce() && !hidden && exists(path))
+                continue;
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} matched {}", rule, path);
+            return applyRule(rule, to, baseRequest, request, response);
+        }
+
+        if (!hidden && serve(path, false, baseRequest, request, response))
+            return true;
+
+        if (isCleanUrls() && !path.endsWith("/") && !path.substring(path.lastIndexOf('/')).contains("."))
+            return serve(path + ".html", false, baseRequest, request, response);
+
+        return false;
+    }
+
+    private boolean applyRule(Rule rule, String to, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        int status = rule.getStatus();
+        if (HttpStatus.isRedirection(status))
+        {
+            String location = to;
+            if (location.startsWith("/"))
+                location = URIUtil.addPaths(request.getContextPath(), location);
+            String query = request.getQueryString();
+            if (query != null && !location.contains("?"))
+                location += "?" + query;
+            baseRequest.setHandled(true);
+            response.setHeader(HttpHeader.LOCATION.asString(), response.encodeRedirectURL(location));
+            response.setStatus(status);
+            return true;
+        }
+
+        boolean error = status != HttpStatus.OK_200;
+        if (error)
+            response.setStatus(status);
+        if (serve(to, error, baseRequest, request, response))
+            return true;
+        if (!error)
+            return false;
+        baseRequest.setHandled(true);
+        response.sendError(status);
+        return true;
+    }
+
+    private boolean serveErrorPage(int status, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        String page = getErrorPage(status);
+        if (page == null || !exists(page))
+            return false;
+        response.resetBuffer();
+        response.setStatus(status);
+        // Error pages are not cached unless configured otherwise.
+        String cacheControl = getCacheControl(page);
+        response.setHeader(HttpHeader.CACHE_CONTROL.asString(), cacheControl == null ? "no-store" : cacheControl);
+        return serve(page, true, baseRequest, request, response);
+    }
+
+    private boolean serve(String path, boolean error, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (getResourceService().doGet(new PathRequest(request, path, error), response))
+        {
+            baseRequest.setHandled(true);
+            return true;
+        }
+        return false;
+    }
+
+    private boolean exists(String path)
+    {
+        try
+        {
+            return getResource(path).exists();
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not resolve {}", path, x);
+            return false;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,rules=%d,aliases=%s}", getClass().getSimpleName(), hashCode(), getState(), getRules().size(), _aliases.keySet());
+    }
+
+    /**
+     * <p>A redirect or rewrite rule.</p>
+     * <p>The path pattern is made of segments: literal segments match exactly,
+     * {@code :name} segments match any single segment, and a final {@code *} segment
+     * matches the rest of the path. The target is either a path in the site or an
+     * absolute URI, where {@code :name} segments and {@code :splat} are replaced
+     * with the matched values.</p>
+     * <p>The status determines what is done when the rule matches:</p>
+     * <ul>
+     * <li>a redirect status, such as {@code 301} (the default) or {@code 302},
+     * redirects the client to the target;</li>
+     * <li>{@code 200} serves the target resource for the path, without redirecting;</li>
+     * <li>an error status, such as {@code 404} or {@code 410}, serves the target resource
+     * with that status.</li>
+     * </ul>
+     * <p>Unless forced, rules only apply to the paths for which no resource exists.</p>
+     */
+    public static class Rule
+    {
+        private final String _from;
+        private final String[] _segments;
+        private final String _to;
+        private final int _status;
+        private final boolean _force;
+
+        public Rule(String from, String to, int status, boolean force)
+        {
+            if (!from.startsWith("/"))
+                throw new IllegalArgumentException("Invalid path pattern: " + from);
+            if (status != HttpStatus.OK_200 && !HttpStatus.isRedirection(status) && !HttpStatus.isClientError(status) && !HttpStatus.isServerError(status))
+                throw new IllegalArgumentException("Invalid status: " + status);
+            if (!HttpStatus.isRedirection(status) && !to.startsWith("/"))
+                throw new IllegalArgumentException("Invalid target path: " + to);
+            _from = from;
+            _segments = from.substring(1).split("/", -1);
+            for (int i = 0; i < _segments.length - 1; ++i)
+            {
+                if ("*".equals(_segments[i]))
+                    throw new IllegalArgumentException("Invalid path pattern: " + from);
+            }
+            _to = Objects.requireNonNull(to);
+            _status = status;
+            _force = force;
+        }
+
+        /**
+         * <p>Parses a line of a redirects file, in the format
+         * {@code from to [status][!]}, where the optional {@code !} forces the rule.</p>
+         * <p>Blank lines and lines starting with {@code #} are ignored.</p>
+         *
+         * @param line the line to parse
+         * @return the rule, or null if the line is blank or a comment
+         * @throws IllegalArgumentException if the line is not a valid rule
+         */
+        public static Rule parse(String line)
+        {
+            String trimmed = line.trim();
+            if (trimmed.isEmpty() || trimmed.startsWith("#"))
+                return null;
+            String[] parts = trimmed.split("\\s+");
+            if (parts.length < 2 || parts.length > 3)
+                throw new IllegalArgumentException("Invalid rule: " + line);
+            int status = HttpStatus.MOVED_PERMANENTLY_301;
+            boolean force = false;
+            if (parts.length == 3)
+            {
+                String code = parts[2];
+                if (code.endsWith("!"))
+                {
+                    force = true;
+                    code = code.substring(0, code.length() - 1);
+                }
+                status = Integer.parseInt(code);
+            }
+            return new Rule(parts[0], parts[1], status, force);
+        }
+
+        public String getFrom()
+        {
+            return _from;
+        }
+
+        public String getTo()
+        {
+            return _to;
+        }
+
+        public int getStatus()
+        {
+            return _status;
+        }
+
+        public boolean isForce()
+        {
+            return _force;
+        }
+
+        /**
+         * @param path the request path
+         * @return the target for the given path, or null if this rule does not match the path
+         */
+        public String apply(String path)
+        {
+            String[] segments = path.substring(1).split("/", -1);
+            Map<String, String> values = new HashMap<>();
+            for (int i = 0; i < _segments.length; ++i)
+            {
+                String segment = _segments[i];
+                if ("*".equals(segment))
+                {
+                    values.put("splat", String.join("/", List.of(segments).subList(Math.min(i, segments.length), segments.length)));
+                    return substitute(values);
+                }
+                if (i >= segments.length)
+                    return null;
+                if (segment.startsWith(":"))
+                {
+                    if (segments[i].isEmpty())
+                        return null;
+                    values.put(segment.substring(1), segments[i]);
+                }
+                else if (!segment.equals(segments[i]))
+                {
+                    return null;
+                }
+            }
+            if (segments.length != _segments.length)
+                return null;
+            return substitute(values);
+        }
+
+        private String substitute(Map<String, String> values)
+        {
+            if (values.isEmpty())
+                return _to;
+            StringBuilder result = new StringBuilder();
+            int start = 0;
+            while (start < _to.length())
+            {
+                int colon = _to.indexOf(':', start);
+                if (colon < 0)
+                {
+                    result.append(_to, start, _to.length());
+                    break;
+                }
+                int end = colon + 1;
+                while (end < _to.length() && (Character.isLetterOrDigit(_to.charAt(end)) || _to.charAt(end) == '_'))
+                {
+                    ++end;
+                }
+                String value = values.get(_to.substring(colon + 1, end));
+                result.append(_to, start, colon);
+                result.append(value == null ? _to.substring(colon, end) : value);
+                start = end;
+            }
+            return result.toString();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s %s %d%s", _from, _to, _status, _force ? "!" : "");
+        }
+    }
+
+    /**
+     * A request wrapper that serves a different path, and ignores
+     * the conditional and range headers when serving an error page.
+     */
+    private static class PathRequest extends HttpServletRequestWrapper
+    {
+        private final String _path;
+        private final boolean _error;
+
+        private PathRequest(HttpServletRequest request, String path, boolean error)
+        {
+            super(request);
+            _path = path;
+            _error = error;
+        }
+
+        @Override
+        public String getServletPath()
+        {
+            return "";
+        }
+
+        @Override
+        public String getPathInfo()
+        {
+            return _path;
+        }
+
+        @Override
+        public String getHeader(String name)
+        {
+            if (_error && isIgnored(name))
+                return null;
+            return super.getHeader(name);
+        }
+
+        @Override
+        public Enumeration<String> getHeaders(String name)
+        {
+            if (_error && isIgnored(name))
+                return Collections.emptyEnumeration();
+            return super.getHeaders(name);
+        }
+
+        private static boolean isIgnored(String name)
+        {
+            return StringUtil.startsWithIgnoreCase(name, "If-") || HttpHeader.RANGE.is(name);
+        }
+    }
+}
@@ -14,9 +14,21 @@
 package org.eclipse.jetty.server.handler;
 
 import java.io.IOException;
+import java.util.Arrays;
+import java.util.Collections;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.TreeMap;
 import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicLong;
 import java.util.concurrent.atomic.LongAdder;
+import java.util.stream.Collectors;
 import javax.servlet.AsyncEvent;
 import javax.servlet.AsyncListener;
 import javax.servlet.ServletException;
@@ -26,21 +38,38 @@ import javax.servlet.http.HttpServletResponse;
 import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.server.AsyncContextEvent;
 import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HandlerInstrumentation;
+import org.eclipse.jetty.server.HandlerProfiler;
 import org.eclipse.jetty.server.HttpChannelState;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
 import org.eclipse.jetty.util.component.Graceful;
 import org.eclipse.jetty.util.statistic.CounterStatistic;
+import org.eclipse.jetty.util.statistic.HistogramStatistic;
 import org.eclipse.jetty.util.statistic.SampleStatistic;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
+/**
+ * <p>A {@link HandlerWrapper} that gathers statistics about the requests it handles.</p>
+ * <p>The statistics are cumulative since the last {@link #statsReset()}, and can
+ * optionally include the percentiles of the request times over a sliding window,
+ * see {@link #setRequestTimeWindow(long)}, and a breakdown of the requests by
+ * path prefix, see {@link #setPathDepth(int)}.</p>
+ */
 @ManagedObject("Request Statistics Gathering")
 public class StatisticsHandler extends HandlerWrapper implements Graceful
 {
+    /**
+     * The key of the per-path statistics of the paths that exceed {@link #getMaxPaths()}.
+     */
+    public static final String OTHER_PATHS = "<other>";
+
     private static final Logger LOG = LoggerFactory.getLogger(StatisticsHandler.class);
     private final AtomicLong _statsStartedAt = new AtomicLong();
     private final Shutdown _shutdown;
@@ -62,8 +91,13 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
     private final LongAdder _responses4xx = new LongAdder();
     private final LongAdder _responses5xx = new LongAdder();
     private final LongAdder _responsesTotalBytes = new LongAdder();
+    private final ConcurrentMap<String, PathStatistics> _pathStats = new ConcurrentHashMap<>();
+    private volatile HistogramStatistic _requestTimeHistogram;
 
     private boolean _gracefulShutdownWaitsForRequests = true;
+    private long _requestTimeWindow;
+    private int _pathDepth;
+    private int _maxPaths = 64;
 
     private final AsyncListener _onCompletion = new AsyncListener()
     {
@@ -93,6 +127,7 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
             _requestStats.decrement();
             _requestTimeStats.record(elapsed);
             updateResponse(request, false);
+            updateRequestTime(request, elapsed, false);
             _asyncWaitStats.decrement();
 
             if (_shutdown.isShutdown())
@@ -137,6 +172,11 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         _responses4xx.reset();
         _responses5xx.reset();
         _responsesTotalBytes.reset();
+
+        HistogramStatistic histogram = _requestTimeHistogram;
+        if (histogram != null)
+            histogram.reset();
+        _pathStats.clear();
     }
 
     @Override
@@ -170,7 +210,7 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         boolean thrownError = false;
         try
         {
-            handler.handle(path, baseRequest, request, response);
+            HandlerInstrumentation.handle(handler, path, baseRequest, request, response);
         }
         catch (Throwable t)
         {
@@ -197,6 +237,7 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
                     _requestStats.decrement();
                     _requestTimeStats.record(dispatched);
                     updateResponse(baseRequest, thrownError);
+                    updateRequestTime(baseRequest, dispatched, thrownError);
                 }
             }
 
@@ -244,12 +285,57 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         _responsesTotalBytes.add(response.getContentCount());
     }
 
+    private void updateRequestTime(Request request, long elapsed, boolean thrownError)
+    {
+        HistogramStatistic histogram = _requestTimeHistogram;
+        if (histogram != null)
+            histogram.record(elapsed);
+
+        if (_pathDepth <= 0)
+            return;
+        String key = pathKey(request.getHttpURI().getDecodedPath());
+        PathStatistics stats = _pathStats.get(key);
+        if (stats == null)
+        {
+            // Limit the cardinality, as paths may be chosen by clients.
+            if (_pathStats.size() >= _maxPaths)
+                key = OTHER_PATHS;
+            stats = _pathStats.computeIfAbsent(key, k -> new PathStatistics(newRequestTimeHistogram()));
+        }
+        boolean failed = thrownError || request.getResponse().getStatus() >= 500;
+        stats.record(elapsed, failed);
+    }
+
+    private String pathKey(String path)
+    {
+        if (path == null || path.isEmpty())
+            return "/";
+        int end = 0;
+        for (int i = 0; i < _pathDepth; i++)
+        {
+            int slash = path.indexOf('/', end + 1);
+            if (slash < 0)
+            {
+                end = path.length();
+                break;
+            }
+            end = slash;
+        }
+        return path.substring(0, end);
+    }
+
+    private HistogramStatistic newRequestTimeHistogram()
+    {
+        return _requestTimeWindow > 0 ? new HistogramStatistic(_requestTimeWindow, TimeUnit.MILLISECONDS) : null;
+    }
+
     @Override
     protected void doStart() throws Exception
     {
         if (getHandler() == null)
             throw new IllegalStateException("StatisticsHandler has no Wrapped Handler");
         _shutdown.cancel();
+        _requestTimeHistogram = newRequestTimeHistogram();
         super.doStart();
         statsReset();
     }
@@ -284,6 +370,75 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         return _gracefulShutdownWaitsForRequests;
     }
 
+    /**
+     * @return the duration in milliseconds of the sliding window of the request time
+     * percentiles, or 0 if the percentiles are not gathered
+     */
+    @ManagedAttribute("duration of the sliding window of the request time percentiles (in ms)")
+    public long getRequestTimeWindow()
+    {
+        return _requestTimeWindow;
+    }
+
+    /**
+     * <p>Sets the duration of the sliding window over which the request time
+     * percentiles are computed, or 0 (the default) to not gather them.</p>
+     * <p>The window is divided in 6 slots, so a 60 seconds window covers the
+     * requests completed in the last 50 to 60 seconds.</p>
+     * <p>This method must be called before this handler is started.</p>
+     *
+     * @param requestTimeWindow the duration of the window in milliseconds
+     */
+    public void setRequestTimeWindow(long requestTimeWindow)
+    {
+        if (isStarted())
+            throw new IllegalStateException(getState());
+        _requestTimeWindow = requestTimeWindow;
+    }
+
+    /**
+     * @return the number of path segments of the per-path statistics, or 0 if they are not gathered
+     */
+    @ManagedAttribute("number of path segments of the per-path statistics")
+    public int getPathDepth()
+    {
+        return _pathDepth;
+    }
+
+    /**
+     * <p>Sets the number of leading path segments that group requests in
+     * the per-path statistics, or 0 (the default) to not gather them.</p>
+     * <p>For example, with a depth of 2, requests for {@code /api/users/42}
+     * and {@code /api/users/43} are both accounted to {@code /api/users}.</p>
+     *
+     * @param pathDepth the number of path segments
+     * @see #setMaxPaths(int)
+     */
+    public void setPathDepth(int pathDepth)
+    {
+        _pathDepth = pathDepth;
+    }
+
+    /**
+     * @return the max number of paths of the per-path statistics
+     */
+    @ManagedAttribute("max number of paths of the per-path statistics")
+    public int getMaxPaths()
+    {
+        return _maxPaths;
+    }
+
+    /**
+     * <p>Sets the max number of paths of the per-path statistics, to bound their
+     * memory usage; requests for other paths are accounted to {@link #OTHER_PATHS}.</p>
+     *
+     * @param maxPaths the max number of paths
+     */
+    public void setMaxPaths(int maxPaths)
+    {
+        _maxPaths = maxPaths;
+    }
+
     /**
      * @return the number of requests handled by this handler
      * since {@link #statsReset()} was last called, excluding
@@ -360,6 +515,107 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         return _requestTimeStats.getStdDev();
     }
 
+    /**
+     * @param percentile the percentile, between 0 and 100
+     * @return the given percentile of the time (in milliseconds) of request handling
+     * over the {@link #getRequestTimeWindow() sliding window}, or 0 if the percentiles
+     * are not gathered
+     */
+    @ManagedOperation(value = "percentile of the request time over the sliding window (in ms)", impact = "INFO")
+    public long getRequestTimePercentile(@Name("percentile") double percentile)
+    {
+        HistogramStatistic histogram = _requestTimeHistogram;
+        return histogram == null ? 0 : histogram.getPercentile(percentile);
+    }
+
+    /**
+     * @return the median time (in milliseconds) of request handling over the sliding window
+     * @see #getRequestTimePercentile(double)
+     */
+    @ManagedAttribute("median request time over the sliding window (in ms)")
+    public long getRequestTimePercentile50()
+    {
+        return getRequestTimePercentile(50);
+    }
+
+    /**
+     * @return the 95th percentile of the time (in milliseconds) of request handling over the sliding window
+     * @see #getRequestTimePercentile(double)
+     */
+    @ManagedAttribute("95th percentile of the request time over the sliding window (in ms)")
+    public long getRequestTimePercentile95()
+    {
+        return getRequestTimePercentile(95);
+    }
+
+    /**
+     * @return the 99th percentile of the time (in milliseconds) of request handling over the sliding window
+     * @see #getRequestTimePercentile(double)
+     */
+    @ManagedAttribute("99th percentile of the request time over the sliding window (in ms)")
+    public long getRequestTimePercentile99()
+    {
+        return getRequestTimePercentile(99);
+    }
+
+    /**
+     * @return the histogram of the request times over the sliding window, or null if the
+     * percentiles are not gathered
+     */
+    public HistogramStatistic getRequestTimeHistogram()
+    {
+        return _requestTimeHistogram;
+    }
+
+    /**
+     * @return the per-path statistics, keyed by path prefix
+     * @see #setPathDepth(int)
+     */
+    public Map<String, PathStatistics> getPathStatistics()
+    {
+        return Collections.unmodifiableMap(new TreeMap<>(_pathStats));
+    }
+
+    /**
+     * @return a description of the per-path statistics, keyed by path prefix
+     */
+    @ManagedAttribute("statistics per path prefix")
+    public Map<String, String> getPathStatisticsDescriptions()
+    {
+        Map<String, String> result = new TreeMap<>();
+        _pathStats.forEach((path, stats) -> result.put(path, stats.toSummary()));
+        return result;
+    }
+
+    /**
+     * @return the profiles of this handler and of its descendant handlers, by decreasing self CPU time,
+     * or an empty list if no {@link HandlerProfiler} is set on the server
+     * @see Server#setHandlerInstrumentation(HandlerInstrumentation)
+     */
+    public List<HandlerProfiler.Profile> getHandlerProfiles()
+    {
+        Server server = getServer();
+        if (server == null || !(server.getHandlerInstrumentation() instanceof HandlerProfiler))
+            return List.of();
+        Set<Handler> handlers = new HashSet<>(Arrays.asList(getChildHandlers()));
+        handlers.add(this);
+        return ((HandlerProfiler)server.getHandlerInstrumentation()).getProfiles().stream()
+            .filter(profile -> handlers.contains(profile.getHandler()))
+            .collect(Collectors.toList());
+    }
+
+    /**
+     * @return a description of the profiles of this handler and of its descendant handlers, keyed by handler
+     * @see #getHandlerProfiles()
+     */
+    @ManagedAttribute("CPU time and allocated bytes per handler")
+    public Map<String, String> getHandlerProfileDescriptions()
+    {
+        Map<String, String> result = new LinkedHashMap<>();
+        getHandlerProfiles().forEach(profile -> result.put(profile.getName(), profile.toSummary()));
+        return result;
+    }
+
     /**
      * @return the number of dispatches seen by this handler
      * since {@link #statsReset()} was last called, excluding
@@ -593,6 +849,13 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         sb.append("Mean request time: ").append(getRequestTimeMean()).append("<br />\n");
         sb.append("Max request time: ").append(getRequestTimeMax()).append("<br />\n");
         sb.append("Request time standard deviation: ").append(getRequestTimeStdDev()).append("<br />\n");
+        if (_requestTimeHistogram != null)
+        {
+            sb.append("Request time percentiles (last ").append(getRequestTimeWindow()).append("ms): ")
+                .append("p50=").append(getRequestTimePercentile50())
+                .append(" p95=").append(getRequestTimePercentile95())
+                .append(" p99=").append(getRequestTimePercentile99()).append("<br />\n");
+        }
 
         sb.append("<h2>Dispatches:</h2>\n");
         sb.append("Total dispatched: ").append(getDispatched()).append("<br />\n");
@@ -616,6 +879,20 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         sb.append("responses thrown: ").append(getResponsesThrown()).append("<br />\n");
         sb.append("Bytes sent total: ").append(getResponsesBytesTotal()).append("<br />\n");
 
+        Map<String, PathStatistics> pathStats = getPathStatistics();
+        if (!pathStats.isEmpty())
+        {
+            sb.append("<h2>Paths:</h2>\n");
+            pathStats.forEach((path, stats) -> sb.append(path).append(": ").append(stats.toSummary()).append("<br />\n"));
+        }
+
+        List<HandlerProfiler.Profile> profiles = getHandlerProfiles();
+        if (!profiles.isEmpty())
+        {
+            sb.append("<h2>Handlers:</h2>\n");
+            profiles.forEach(profile -> sb.append(profile.getName()).append(": ").append(profile.toSummary()).append("<br />\n"));
+        }
+
         return sb.toString();
     }
 
@@ -637,4 +914,103 @@ public class StatisticsHandler extends HandlerWrapper implements Graceful
         return String.format("%s@%x{%s,r=%d,d=%d}", getClass().getSimpleName(), hashCode(), getState(), _requestStats.getCurrent(), _dispatchedStats.getCurrent());
     }
 
+    /**
+     * <p>The statistics of the requests for a path prefix.</p>
+     *
+     * @see #setPathDepth(int)
+     */
+    public static class PathStatistics
+    {
+        private final LongAdder _requests = new LongAdder();
+        private final LongAdder _failures = new LongAdder();
+        private final SampleStatistic _requestTimeStats = new SampleStatistic();
+        private final HistogramStatistic _requestTimeHistogram;
+
+        private PathStatistics(HistogramStatistic requestTimeHistogram)
+        {
+            _requestTimeHistogram = requestTimeHistogram;
+        }
+
+        private void record(long elapsed, boolean failed)
+        {
+            _requests.increment();
+            if (failed)
+                _failures.increment();
+            _requestTimeStats.record(elapsed);
+            if (_requestTimeHistogram != null)
+                _requestTimeHistogram.record(elapsed);
+        }
+
+        /**
+         * @return the number of requests
+         */
+        public long getRequests()
+        {
+            return _requests.longValue();
+        }
+
+        /**
+         * @return the number of requests that threw an exception or had a 5xx response status
+         */
+        public long getFailures()
+        {
+            return _failures.longValue();
+        }
+
+        /**
+         * @return the total time (in milliseconds) of request handling
+         */
+        public long getRequestTimeTotal()
+        {
+            return _requestTimeStats.getTotal();
+        }
+
+        /**
+         * @return the maximum time (in milliseconds) of request handling
+         */
+        public long getRequestTimeMax()
+        {
+            return _requestTimeStats.getMax();
+        }
+
+        /**
+         * @return the mean time (in milliseconds) of request handling
+         */
+        public double getRequestTimeMean()
+        {
+            return _requestTimeStats.getMean();
+        }
+
+        /**
+         * @return the histogram of the request times over the sliding window, or null if the
+         * percentiles are not gathered
+         */
+        public HistogramStatistic getRequestTimeHistogram()
+        {
+            return _requestTimeHistogram;
+        }
+
+        private String toSummary()
+        {
+            StringBuilder builder = new StringBuilder();
+            builder.append("requests=").append(getRequests())
+                .append(",failures=").append(getFailures())
+                .append(",mean=").append(getRequestTimeMean())
+                .append(",max=").append(getRequestTimeMax());
+            if (_requestTimeHistogram != null)
+            {
+                HistogramStatistic.Snapshot snapshot = _requestTimeHistogram.snapshot();
+                builder.append(",p50=").append(snapshot.getPercentile(50))
+                    .append(",p95=").append(snapshot.getPercentile(95))
+                    .append(",p99=").append(snapshot.getPercentile(99));
+            }
+            return builder.toString();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x{%s}", getClass().getSimpleName(), hashCode(), toSummary());
+        }
+    }
 }
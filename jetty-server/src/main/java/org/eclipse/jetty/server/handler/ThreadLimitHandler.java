@@ -18,12 +18,20 @@ import java.io.IOException;
 import java.net.InetAddress;
 import java.net.InetSocketAddress;
 import java.util.ArrayDeque;
+import java.util.ArrayList;
 import java.util.Deque;
+import java.util.EventListener;
+import java.util.List;
+import java.util.Objects;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
-import java.util.concurrent.ExecutionException;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.regex.Pattern;
 import javax.servlet.AsyncContext;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
 import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
@@ -31,6 +39,7 @@ import javax.servlet.http.HttpServletResponse;
 import org.eclipse.jetty.http.HostPortHttpField;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.http.QuotedCSV;
 import org.eclipse.jetty.server.ForwardedRequestCustomizer;
 import org.eclipse.jetty.server.Request;
@@ -45,9 +54,11 @@ import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 /**
- * <p>Handler to limit the threads per IP address for DOS protection</p>
- * <p>The ThreadLimitHandler applies a limit to the number of Threads
- * that can be used simultaneously per remote IP address.
+ * <p>Handler to limit the concurrent requests per IP address for DOS protection</p>
+ * <p>The ThreadLimitHandler applies a limit to the number of requests
+ * that can be processed concurrently per remote IP address, including
+ * requests that are processed asynchronously, which hold their permit
+ * until they complete.
  * </p>
  * <p>The handler makes a determination of the remote IP separately to
  * any that may be made by the {@link ForwardedRequestCustomizer} or similar:
@@ -56,26 +67,39 @@ import org.slf4j.LoggerFactory;
  * of forwarded header.   This is on the assumption that a trusted local proxy
  * will produce only a single forwarded header and that any additional
  * headers are likely from untrusted client side proxies.</li>
- * <li>If multiple instances of a forwarded header are provided, this
- * handler will use the right-most instance, which will have been set from
- * the trusted local proxy</li>
+ * <li>If no trusted proxies are configured and multiple instances of a forwarded
+ * header are provided, this handler will use the right-most instance, which
+ * will have been set from the trusted local proxy</li>
+ * <li>If {@link #addTrustedProxy(String) trusted proxies} are configured, the
+ * forwarded header is only used if the request was received from a trusted proxy,
+ * and its instances are walked from right to left, skipping the trusted proxies,
+ * until an address that is not trusted is found, which is the remote IP.</li>
  * </ul>
- * Requests in excess of the limit will be asynchronously suspended until
- * a thread is available.
+ * Requests in excess of the limit will be asynchronously suspended, without
+ * blocking a thread, until a permit is released by another request of the same
+ * remote IP; if the number of suspended requests exceeds the
+ * {@link #getMaxQueueSize() max queue size}, then requests are rejected with
+ * a {@code 429 Too Many Requests} response.
+ * <p>The {@link Listener}s of this handler are notified when a remote IP saturates
+ * its limit, so that the excess requests are queued, and when it stops being saturated.</p>
  * <p>This is a simpler alternative to DosFilter</p>
  */
 public class ThreadLimitHandler extends HandlerWrapper
 {
     private static final Logger LOG = LoggerFactory.getLogger(ThreadLimitHandler.class);
+    private static final Pattern IP_LITERAL = Pattern.compile("[0-9a-fA-F.:]+");
 
     private static final String REMOTE = "o.e.j.s.h.TLH.REMOTE";
     private static final String PERMIT = "o.e.j.s.h.TLH.PASS";
     private final boolean _rfc7239;
     private final String _forwardedHeader;
     private final IncludeExcludeSet<String, InetAddress> _includeExcludeSet = new IncludeExcludeSet<>(InetAddressSet.class);
+    private final InetAddressSet _trustedProxies = new InetAddressSet();
     private final ConcurrentMap<String, Remote> _remotes = new ConcurrentHashMap<>();
+    private final List<Listener> _listeners = new CopyOnWriteArrayList<>();
     private volatile boolean _enabled;
     private int _threadLimit = 10;
+    private int _maxQueueSize = -1;
 
     public ThreadLimitHandler()
     {
@@ -114,7 +138,7 @@ public class ThreadLimitHandler extends HandlerWrapper
         LOG.info(String.format("ThreadLimitHandler enable=%b limit=%d include=%s", _enabled, _threadLimit, _includeExcludeSet));
     }
 
-    @ManagedAttribute("The maximum threads that can be dispatched per remote IP")
+    @ManagedAttribute("The maximum concurrent requests per remote IP")
     public int getThreadLimit()
     {
         return _threadLimit;
@@ -147,6 +171,21 @@ public class ThreadLimitHandler extends HandlerWrapper
         _threadLimit = threadLimit;
     }
 
+    @ManagedAttribute("The maximum requests queued per remote IP, or -1 for unlimited")
+    public int getMaxQueueSize()
+    {
+        return _maxQueueSize;
+    }
+
+    /**
+     * @param maxQueueSize the maximum number of requests queued per remote IP
+     * while waiting for a permit, or -1 for an unlimited queue
+     */
+    public void setMaxQueueSize(int maxQueueSize)
+    {
+        _maxQueueSize = maxQueueSize;
+    }
+
     @ManagedOperation("Include IP in thread limits")
     public void include(String inetAddressPattern)
     {
@@ -159,6 +198,47 @@ public class ThreadLimitHandler extends HandlerWrapper
         _includeExcludeSet.exclude(inetAddressPattern);
     }
 
+    /**
+     * <p>Adds an address pattern, in the format supported by {@link InetAddressSet},
+     * of the proxies that are trusted to add the forwarded header.</p>
+     *
+     * @param inetAddressPattern the address pattern of trusted proxies
+     */
+    @ManagedOperation("Trust the proxy IP to add forwarded headers")
+    public void addTrustedProxy(@Name("inetAddressPattern") String inetAddressPattern)
+    {
+        _trustedProxies.add(inetAddressPattern);
+    }
+
+    /**
+     * @param inetAddressPatterns the address patterns of trusted proxies
+     * @see #addTrustedProxy(String)
+     */
+    public void addTrustedProxies(String... inetAddressPatterns)
+    {
+        for (String inetAddressPattern : inetAddressPatterns)
+        {
+            addTrustedProxy(inetAddressPattern);
+        }
+    }
+
+    /**
+     * @param listener the listener to notify of the saturation of remote IPs
+     */
+    public void addListener(Listener listener)
+    {
+        _listeners.add(Objects.requireNonNull(listener));
+    }
+
+    /**
+     * @param listener the listener to remove
+     * @return whether the listener was removed
+     */
+    public boolean removeListener(Listener listener)
+    {
+        return _listeners.remove(listener);
+    }
+
     @Override
     public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
     {
@@ -167,70 +247,76 @@ public class ThreadLimitHandler extends HandlerWrapper
         {
             // if disabled, handle normally
             super.handle(target, baseRequest, request, response);
+            return;
         }
-        else
+
+        // Get the remote address of the request
+        Remote remote = getRemote(baseRequest);
+        if (remote == null)
         {
-            // Get the remote address of the request
-            Remote remote = getRemote(baseRequest);
-            if (remote == null)
+            // if remote is not known, handle normally
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        // Do we already have a permit from a previous dispatch?
+        Permit permit = (Permit)baseRequest.getAttribute(PERMIT);
+        if (permit == null)
+        {
+            // No, then lets try to acquire one
+            CompletableFuture<Permit> futurePermit = remote.acquire();
+
+            // Is the queue full?
+            if (futurePermit == null)
             {
-                // if remote is not known, handle normally
-                super.handle(target, baseRequest, request, response);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Rejected {} {}", remote, target);
+                baseRequest.setHandled(true);
+                response.sendError(HttpStatus.TOO_MANY_REQUESTS_429);
+                return;
             }
-            else
+
+            // Did we get a permit?
+            if (!futurePermit.isDone())
             {
-                // Do we already have a future permit from a previous invocation?
-                Closeable permit = (Closeable)baseRequest.getAttribute(PERMIT);
-                try
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Threadlimited {} {}", remote, target);
+                // No, lets asynchronously suspend the request
+                AsyncContext async = baseRequest.startAsync();
+                // let's never timeout the async.  If this is a DOS, then good to make them wait, if this is not
+                // then give them maximum time to get a permit.
+                async.setTimeout(0);
+                async.addListener(new Parked(futurePermit));
+
+                // dispatch the request when we do eventually get a permit
+                futurePermit.thenAccept(p ->
                 {
-                    if (permit != null)
-                    {
-                        // Yes, remove it from any future async cycles.
-                        baseRequest.removeAttribute(PERMIT);
-                    }
-                    else
-                    {
-                        // No, then lets try to acquire one
-                        CompletableFuture<Closeable> futurePermit = remote.acquire();
-
-                        // Did we get a permit?
-                        if (futurePermit.isDone())
-                        {
-                            // yes
-                            permit = futurePermit.get();
-                        }
-                        else
-                        {
-                            if (LOG.isDebugEnabled())
-                                LOG.debug("Threadlimited {} {}", remote, target);
-                            // No, lets asynchronously suspend the request
-                            AsyncContext async = baseRequest.startAsync();
-                            // let's never timeout the async.  If this is a DOS, then good to make them wait, if this is not
-                            // then give them maximum time to get a thread.
-                            async.setTimeout(0);
-
-                            // dispatch the request when we do eventually get a pass
-                            futurePermit.thenAccept(c ->
-                            {
-                                baseRequest.setAttribute(PERMIT, c);
-                                async.dispatch();
-                            });
-                            return;
-                        }
-                    }
+                    baseRequest.setAttribute(PERMIT, p);
+                    async.dispatch();
+                });
+                return;
+            }
 
-                    // Use the permit
-                    super.handle(target, baseRequest, request, response);
-                }
-                catch (InterruptedException | ExecutionException e)
-                {
-                    throw new ServletException(e);
-                }
-                finally
-                {
-                    if (permit != null)
-                        permit.close();
-                }
+            permit = futurePermit.join();
+            baseRequest.setAttribute(PERMIT, permit);
+        }
+
+        try
+        {
+            // Use the permit
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (baseRequest.isAsyncStarted())
+            {
+                // Keep the permit until the request completes.
+                permit.listen(baseRequest.getAsyncContext());
+            }
+            else
+            {
+                baseRequest.removeAttribute(PERMIT);
+                permit.close();
             }
         }
     }
@@ -266,22 +352,65 @@ public class ThreadLimitHandler extends HandlerWrapper
 
     protected String getRemoteIP(Request baseRequest)
     {
+        // Do not use the request methods, as they may have been lied to by the
+        // RequestCustomizer!
+        String peer = null;
+        InetSocketAddress inetAddr = baseRequest.getHttpChannel().getRemoteAddress();
+        if (inetAddr != null && inetAddr.getAddress() != null)
+            peer = inetAddr.getAddress().getHostAddress();
+
         // Do we have a forwarded header set?
         if (_forwardedHeader != null && !_forwardedHeader.isEmpty())
         {
-            // Yes, then try to get the remote IP from the header
-            String remote = _rfc7239 ? getForwarded(baseRequest) : getXForwardedFor(baseRequest);
-            if (remote != null && !remote.isEmpty())
+            if (_trustedProxies.isEmpty())
+            {
+                // Yes, then try to get the remote IP from the header
+                String remote = _rfc7239 ? getForwarded(baseRequest) : getXForwardedFor(baseRequest);
+                if (remote != null && !remote.isEmpty())
+                    return remote;
+            }
+            else
+            {
+                // Walk the chain from the closest hop, stopping at the first
+                // address that is not trusted and therefore may have lied.
+                List<String> chain = _rfc7239 ? getForwardedChain(baseRequest) : getXForwardedForChain(baseRequest);
+                String remote = peer;
+                for (int i = chain.size(); i-- > 0;)
+                {
+                    if (remote == null || !isTrustedProxy(remote))
+                        break;
+                    String hop = chain.get(i);
+                    // The trusted proxy does not know the remote IP,
+                    // so it is the best key we can have.
+                    if (hop == null || hop.isEmpty())
+                        break;
+                    remote = hop;
+                }
                 return remote;
+            }
         }
 
         // If no remote IP from a header, determine it directly from the channel
-        // Do not use the request methods, as they may have been lied to by the 
-        // RequestCustomizer!
-        InetSocketAddress inetAddr = baseRequest.getHttpChannel().getRemoteAddress();
-        if (inetAddr != null && inetAddr.getAddress() != null)
-            return inetAddr.getAddress().getHostAddress();
-        return null;
+        return peer;
+    }
+
+    private boolean isTrustedProxy(String ip)
+    {
+        if (ip.startsWith("[") && ip.endsWith("]"))
+            ip = ip.substring(1, ip.length() - 1);
+        // Only test IP literals, for example not RFC 7239 obfuscated identifiers,
+        // to avoid the DNS lookups that InetAddress.getByName() would perform.
+        if (!IP_LITERAL.matcher(ip).matches())
+            return false;
+        try
+        {
+            return _trustedProxies.test(InetAddress.getByName(ip));
+        }
+        catch (Exception e)
+        {
+            LOG.trace("IGNORED", e);
+            return false;
+        }
     }
 
     private String getForwarded(Request request)
@@ -289,6 +418,12 @@ public class ThreadLimitHandler extends HandlerWrapper
         // Get the right most Forwarded for value.
         // This is the value from the closest proxy and the only one that
         // can be trusted.
+        List<String> chain = getForwardedChain(request);
+        return chain.isEmpty() ? null : chain.get(chain.size() - 1);
+    }
+
+    private List<String> getForwardedChain(Request request)
+    {
         RFC7239 rfc7239 = new RFC7239();
         for (HttpField field : request.getHttpFields())
         {
@@ -296,10 +431,12 @@ public class ThreadLimitHandler extends HandlerWrapper
                 rfc7239.addValue(field.getValue());
         }
 
-        if (rfc7239.getFor() != null)
-            return new HostPortHttpField(rfc7239.getFor()).getHost();
-
-        return null;
+        List<String> chain = new ArrayList<>();
+        for (String value : rfc7239.getFor())
+        {
+            chain.add(value == null ? null : new HostPortHttpField(value).getHost());
+        }
+        return chain;
     }
 
     private String getXForwardedFor(Request request)
@@ -321,14 +458,115 @@ public class ThreadLimitHandler extends HandlerWrapper
         return (comma >= 0) ? forwardedFor.substring(comma + 1).trim() : forwardedFor;
     }
 
-    private static final class Remote implements Closeable
+    private List<String> getXForwardedForChain(Request request)
+    {
+        List<String> chain = new ArrayList<>();
+        for (HttpField field : request.getHttpFields())
+        {
+            if (_forwardedHeader.equalsIgnoreCase(field.getName()))
+            {
+                for (String value : StringUtil.csvSplit(field.getValue()))
+                {
+                    chain.add("unknown".equalsIgnoreCase(value) ? null : value);
+                }
+            }
+        }
+        return chain;
+    }
+
+    private void notifySaturated(String ip)
+    {
+        for (Listener listener : _listeners)
+        {
+            try
+            {
+                listener.onSaturated(this, ip);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyUnsaturated(String ip)
+    {
+        for (Listener listener : _listeners)
+        {
+            try
+            {
+                listener.onUnsaturated(this, ip);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private void notifyRejected(String ip)
+    {
+        for (Listener listener : _listeners)
+        {
+            try
+            {
+                listener.onRejected(this, ip);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    /**
+     * <p>A listener of the saturation of the limit of remote IPs.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when a request is queued because
+         * the given remote IP reached its limit of concurrent requests,
+         * and no other request of that remote IP was already queued.</p>
+         *
+         * @param handler the handler
+         * @param ip the remote IP
+         */
+        public default void onSaturated(ThreadLimitHandler handler, String ip)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when all the queued requests of
+         * the given remote IP, previously saturated, have been dispatched.</p>
+         *
+         * @param handler the handler
+         * @param ip the remote IP
+         */
+        public default void onUnsaturated(ThreadLimitHandler handler, String ip)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a request of the given remote IP
+         * is rejected because the max queue size has been reached.</p>
+         *
+         * @param handler the handler
+         * @param ip the remote IP
+         * @see #getMaxQueueSize()
+         */
+        public default void onRejected(ThreadLimitHandler handler, String ip)
+        {
+        }
+    }
+
+    private final class Remote
     {
         private final String _ip;
         private final int _limit;
         private final AutoLock _lock = new AutoLock();
         private int _permits;
-        private Deque<CompletableFuture<Closeable>> _queue = new ArrayDeque<>();
-        private final CompletableFuture<Closeable> _permitted = CompletableFuture.completedFuture(this);
+        private final Deque<CompletableFuture<Permit>> _queue = new ArrayDeque<>();
 
         public Remote(String ip, int limit)
         {
@@ -336,8 +574,13 @@ public class ThreadLimitHandler extends HandlerWrapper
             _limit = limit;
         }
 
-        public CompletableFuture<Closeable> acquire()
+        /**
+         * @return a future permit, or null if the request must be rejected
+         */
+        public CompletableFuture<Permit> acquire()
         {
+            boolean saturated;
+            CompletableFuture<Permit> pass;
             try (AutoLock lock = _lock.lock())
             {
                 // Do we have available passes?
@@ -345,20 +588,35 @@ public class ThreadLimitHandler extends HandlerWrapper
                 {
                     // Yes - increment the allocated passes
                     _permits++;
-                    // return the already completed future
-                    return _permitted; // TODO is it OK to share/reuse this?
+                    return CompletableFuture.completedFuture(new Permit(this));
                 }
 
-                // No pass available, so queue a new future 
-                CompletableFuture<Closeable> pass = new CompletableFuture<>();
-                _queue.addLast(pass);
-                return pass;
+                int maxQueueSize = getMaxQueueSize();
+                if (maxQueueSize >= 0 && _queue.size() >= maxQueueSize)
+                {
+                    pass = null;
+                    saturated = false;
+                }
+                else
+                {
+                    // No pass available, so queue a new future
+                    saturated = _queue.isEmpty();
+                    pass = new CompletableFuture<>();
+                    _queue.addLast(pass);
+                }
             }
+
+            if (pass == null)
+                notifyRejected(_ip);
+            else if (saturated)
+                notifySaturated(_ip);
+            return pass;
         }
 
-        @Override
-        public void close()
+        private void release()
         {
+            CompletableFuture<Permit> next = null;
+            boolean unsaturated = false;
             try (AutoLock lock = _lock.lock())
             {
                 // reduce the allocated passes
@@ -366,23 +624,36 @@ public class ThreadLimitHandler extends HandlerWrapper
                 while (true)
                 {
                     // Are there any future passes waiting?
-                    CompletableFuture<Closeable> permit = _queue.pollFirst();
+                    CompletableFuture<Permit> permit = _queue.pollFirst();
 
                     // No - we are done
                     if (permit == null)
                         break;
 
-                    // Yes - if we can complete them, we are done
-                    if (permit.complete(this))
+                    // Yes - if it has not been cancelled, we are done
+                    if (!permit.isDone())
                     {
                         _permits++;
+                        next = permit;
+                        unsaturated = _queue.isEmpty();
                         break;
                     }
 
-                    // Somebody else must have completed/failed that future pass,
+                    // Somebody else must have cancelled that future pass,
                     // so let's try for another.
+                    unsaturated = _queue.isEmpty();
                 }
             }
+
+            // Complete outside the lock, as it dispatches the request.
+            if (next != null && !next.complete(new Permit(this)))
+            {
+                // Cancelled concurrently, try for another.
+                release();
+                return;
+            }
+            if (unsaturated)
+                notifyUnsaturated(_ip);
         }
 
         @Override
@@ -395,16 +666,106 @@ public class ThreadLimitHandler extends HandlerWrapper
         }
     }
 
+    /**
+     * <p>A permit held by a request until it completes.</p>
+     */
+    private static final class Permit implements Closeable, AsyncListener
+    {
+        private final AtomicBoolean _closed = new AtomicBoolean();
+        private final Remote _remote;
+        private boolean _listening;
+
+        private Permit(Remote remote)
+        {
+            _remote = remote;
+        }
+
+        private void listen(AsyncContext async)
+        {
+            // The listener re-registers itself on every startAsync().
+            if (_listening)
+                return;
+            _listening = true;
+            async.addListener(this);
+        }
+
+        @Override
+        public void close()
+        {
+            if (_closed.compareAndSet(false, true))
+                _remote.release();
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            close();
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+    }
+
+    /**
+     * <p>Listens to a request queued while waiting for a permit.</p>
+     */
+    private static final class Parked implements AsyncListener
+    {
+        private final CompletableFuture<Permit> _futurePermit;
+
+        private Parked(CompletableFuture<Permit> futurePermit)
+        {
+            _futurePermit = futurePermit;
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            // If the request completed while parked, give up the permit.
+            if (!_futurePermit.cancel(false))
+                _futurePermit.thenAccept(Permit::close);
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+            _futurePermit.cancel(false);
+        }
+
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+        }
+    }
+
     private static final class RFC7239 extends QuotedCSV
     {
-        String _for;
+        private final List<String> _for = new ArrayList<>();
 
         private RFC7239()
         {
             super(false);
         }
 
-        String getFor()
+        List<String> getFor()
         {
             return _for;
         }
@@ -419,12 +780,12 @@ public class ThreadLimitHandler extends HandlerWrapper
                 {
                     String value = buffer.substring(paramValue);
 
-                    // if unknown, clear any leftward values
+                    // if unknown, record an unknown hop
                     if ("unknown".equalsIgnoreCase(value))
-                        _for = null;
+                        _for.add(null);
                         // Otherwise accept IP or token(starting with '_') as remote keys
                     else
-                        _for = value;
+                        _for.add(value);
                 }
             }
         }
@@ -0,0 +1,537 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.Objects;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentMap;
+import java.util.concurrent.TimeUnit;
+import java.util.function.Function;
+import javax.servlet.AsyncEvent;
+import javax.servlet.AsyncListener;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.AsyncContextEvent;
+import org.eclipse.jetty.server.Authentication;
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that accounts the bytes received and sent per key, for example
+ * for usage based billing of the tenants of a gateway.</p>
+ * <p>Requests are grouped by a key extracted from the request by a
+ * {@link #setKeyExtractor(Function) key extractor}, by default the context
+ * path; requests for which the key extractor returns {@code null} are not
+ * accounted. The key is extracted when the request enters this handler, so
+ * this handler must be placed after the handlers that provide the key, for
+ * example within the {@code SecurityHandler} when the key is the
+ * {@link #user() authenticated user}.</p>
+ * <p>The bytes received are the size of the request line and headers plus
+ * the request content bytes received; the bytes sent are the size of the
+ * response status line and headers plus the response content bytes.
+ * The sizes of the lines and headers are those of their HTTP/1.1
+ * representation, also for other protocols.</p>
+ * <p>Besides the totals, the usage is accounted over fixed
+ * {@link #setPeriod(long) periods} aligned to the epoch, and the
+ * {@link Usage#getLastRollup() rollup} of the last completed period is kept.
+ * The usage of the current period may be enforced by a {@link UsageQuotaHandler}.</p>
+ * <p>At most {@link #setMaxKeys(int) maxKeys} keys are tracked, further keys
+ * are accounted under the {@link #OTHER_KEY} key.</p>
+ */
+@ManagedObject("Usage accounting handler")
+public class UsageAccountingHandler extends HandlerWrapper
+{
+    /**
+     * The key under which requests are accounted when {@link #getMaxKeys() maxKeys} is reached.
+     */
+    public static final String OTHER_KEY = "_other";
+    private static final Logger LOG = LoggerFactory.getLogger(UsageAccountingHandler.class);
+    private static final String KEY_ATTRIBUTE = UsageAccountingHandler.class.getName() + ".key";
+
+    private final ConcurrentMap<String, Usage> _usages = new ConcurrentHashMap<>();
+    private final AsyncListener _onCompletion = new AsyncListener()
+    {
+        @Override
+        public void onStartAsync(AsyncEvent event)
+        {
+            event.getAsyncContext().addListener(this);
+        }
+
+        @Override
+        public void onTimeout(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onError(AsyncEvent event)
+        {
+        }
+
+        @Override
+        public void onComplete(AsyncEvent event)
+        {
+            account(((AsyncContextEvent)event).getHttpChannelState().getBaseRequest());
+        }
+    };
+    private Function<Request, String> _keyExtractor = contextPath();
+    private long _period = TimeUnit.HOURS.toMillis(1);
+    private int _maxKeys = 10_000;
+
+    public Function<Request, String> getKeyExtractor()
+    {
+        return _keyExtractor;
+    }
+
+    /**
+     * @param keyExtractor the function that extracts the accounting key from the request,
+     * returning {@code null} if the request must not be accounted
+     * @see #contextPath()
+     * @see #virtualHost()
+     * @see #user()
+     * @see #header(String)
+     */
+    public void setKeyExtractor(Function<Request, String> keyExtractor)
+    {
+        _keyExtractor = Objects.requireNonNull(keyExtractor);
+    }
+
+    @ManagedAttribute("The accounting period in milliseconds")
+    public long getPeriod()
+    {
+        return _period;
+    }
+
+    /**
+     * @param period the accounting period in milliseconds
+     */
+    public void setPeriod(long period)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        if (period <= 0)
+            throw new IllegalArgumentException("Invalid period " + period);
+        _period = period;
+    }
+
+    @ManagedAttribute("The max number of keys tracked")
+    public int getMaxKeys()
+    {
+        return _maxKeys;
+    }
+
+    public void setMaxKeys(int maxKeys)
+    {
+        _maxKeys = maxKeys;
+    }
+
+    @ManagedAttribute("The number of keys currently tracked")
+    public int getKeys()
+    {
+        return _usages.size();
+    }
+
+    /**
+     * @param key the accounting key
+     * @return the usage for the given key, or null if no request has been accounted for the key
+     */
+    public Usage getUsage(String key)
+    {
+        return _usages.get(key);
+    }
+
+    /**
+     * @return the usages of all the keys
+     */
+    public Collection<Usage> getUsages()
+    {
+        return new ArrayList<>(_usages.values());
+    }
+
+    @ManagedOperation(value = "Resets the usage of all the keys", impact = "ACTION")
+    public void resetUsages()
+    {
+        _usages.clear();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpChannelState state = baseRequest.getHttpChannelState();
+        if (!state.isInitial())
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        String key = _keyExtractor.apply(baseRequest);
+        if (key == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setAttribute(KEY_ATTRIBUTE, key);
+        try
+        {
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            if (state.isAsyncStarted())
+                state.addListener(_onCompletion);
+            else
+                account(baseRequest);
+        }
+    }
+
+    private void account(Request request)
+    {
+        Object key = request.getAttribute(KEY_ATTRIBUTE);
+        if (key == null)
+            return;
+        request.removeAttribute(KEY_ATTRIBUTE);
+
+        long received = requestHeadersSize(request) + request.getHttpInput().getContentReceived();
+        Response response = request.getResponse();
+        long sent = responseHeadersSize(response) + response.getContentCount();
+        Usage usage = getOrCreateUsage(key.toString());
+        usage.record(received, sent);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Accounted received={} sent={} for {} {}", received, sent, usage.getKey(), request);
+    }
+
+    private Usage getOrCreateUsage(String key)
+    {
+        Usage usage = _usages.get(key);
+        if (usage != null)
+            return usage;
+        if (_usages.size() >= _maxKeys)
+            key = OTHER_KEY;
+        return _usages.computeIfAbsent(key, Usage::new);
+    }
+
+    private static long requestHeadersSize(Request request)
+    {
+        String query = request.getQueryString();
+        // Method, URI, protocol, separators and CRLF.
+        long size = request.getMethod().length() + request.getRequestURI().length() + request.getProtocol().length() + 4;
+        if (query != null)
+            size += 1 + query.length();
+        return size + fieldsSize(request.getHttpFields());
+    }
+
+    private static long responseHeadersSize(Response response)
+    {
+        int status = response.getStatus();
+        String reason = response.getReason();
+        if (reason == null)
+            reason = HttpStatus.getMessage(status);
+        // Protocol, status code, reason, separators and CRLF.
+        long size = 8 + 3 + reason.length() + 4;
+        return size + fieldsSize(response.getHttpFields());
+    }
+
+    private static long fieldsSize(HttpFields fields)
+    {
+        long size = 2;
+        if (fields != null)
+        {
+            for (HttpField field : fields)
+            {
+                String value = field.getValue();
+                size += field.getName().length() + 4 + (value == null ? 0 : value.length());
+            }
+        }
+        return size;
+    }
+
+    /**
+     * Gets the current time used to determine the accounting period.
+     *
+     * @return the current time in milliseconds since the epoch
+     */
+    protected long currentTimeMillis()
+    {
+        return System.currentTimeMillis();
+    }
+
+    /**
+     * @return a key extractor that returns the context path, or {@code /} for the root context
+     */
+    public static Function<Request, String> contextPath()
+    {
+        return request ->
+        {
+            String contextPath = request.getContextPath();
+            return contextPath == null || contextPath.isEmpty() ? "/" : contextPath;
+        };
+    }
+
+    /**
+     * @return a key extractor that returns the virtual host of the request
+     */
+    public static Function<Request, String> virtualHost()
+    {
+        return Request::getServerName;
+    }
+
+    /**
+     * @return a key extractor that returns the name of the authenticated user,
+     * so that requests that are not authenticated are not accounted
+     */
+    public static Function<Request, String> user()
+    {
+        return request ->
+        {
+            Authentication authentication = request.getAuthentication();
+            if (authentication instanceof Authentication.User)
+                return ((Authentication.User)authentication).getUserIdentity().getUserPrincipal().getName();
+            return null;
+        };
+    }
+
+    /**
+     * @param name the request header name, for example the header carrying the API key
+     * @return a key extractor that returns the value of the given request header
+     */
+    public static Function<Request, String> header(String name)
+    {
+        return request -> request.getHeader(name);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[period=%dms,keys=%d]", getClass().getSimpleName(), hashCode(), _period, _usages.size());
+    }
+
+    /**
+     * <p>The usage accounted for a key.</p>
+     */
+    public class Usage
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final String _key;
+        private long _requests;
+        private long _received;
+        private long _sent;
+        private long _periodStart;
+        private long _periodRequests;
+        private long _periodReceived;
+        private long _periodSent;
+        private Rollup _lastRollup;
+
+        private Usage(String key)
+        {
+            _key = key;
+            _periodStart = periodStart(currentTimeMillis());
+        }
+
+        public String getKey()
+        {
+            return _key;
+        }
+
+        /**
+         * @return the total number of requests
+         */
+        public long getRequests()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _requests;
+            }
+        }
+
+        /**
+         * @return the total number of bytes received
+         */
+        public long getBytesReceived()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _received;
+            }
+        }
+
+        /**
+         * @return the total number of bytes sent
+         */
+        public long getBytesSent()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return _sent;
+            }
+        }
+
+        /**
+         * @return the usage of the current period, that ends at {@link Rollup#getEnd()}
+         */
+        public Rollup getCurrentPeriod()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                roll();
+                return new Rollup(_key, _periodStart, _periodStart + _period, _periodRequests, _periodReceived, _periodSent);
+            }
+        }
+
+        /**
+         * @return the usage of the last completed period, or null if no period has completed yet
+         */
+        public Rollup getLastRollup()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                roll();
+                return _lastRollup;
+            }
+        }
+
+        private void record(long received, long sent)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                roll();
+                _requests++;
+                _received += received;
+                _sent += sent;
+                _periodRequests++;
+                _periodReceived += received;
+                _periodSent += sent;
+            }
+        }
+
+        private void roll()
+        {
+            assert _lock.isHeldByCurrentThread();
+            long start = periodStart(currentTimeMillis());
+            if (start == _periodStart)
+                return;
+            long previous = start - _period;
+            // The previous period may have had no traffic at all.
+            if (previous == _periodStart)
+                _lastRollup = new Rollup(_key, _periodStart, start, _periodRequests, _periodReceived, _periodSent);
+            else
+                _lastRollup = new Rollup(_key, previous, start, 0, 0, 0);
+            _periodStart = start;
+            _periodRequests = 0;
+            _periodReceived = 0;
+            _periodSent = 0;
+        }
+
+        private long periodStart(long now)
+        {
+            return now - Math.floorMod(now, _period);
+        }
+
+        @Override
+        public String toString()
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                return String.format("%s@%x[%s,requests=%d,received=%d,sent=%d]", getClass().getSimpleName(), hashCode(), _key, _requests, _received, _sent);
+            }
+        }
+    }
+
+    /**
+     * <p>The usage of a key over a period.</p>
+     */
+    public static class Rollup
+    {
+        private final String _key;
+        private final long _start;
+        private final long _end;
+        private final long _requests;
+        private final long _received;
+        private final long _sent;
+
+        private Rollup(String key, long start, long end, long requests, long received, long sent)
+        {
+            _key = key;
+            _start = start;
+            _end = end;
+            _requests = requests;
+            _received = received;
+            _sent = sent;
+        }
+
+        public String getKey()
+        {
+            return _key;
+        }
+
+        /**
+         * @return the start of the period, inclusive, in milliseconds since the epoch
+         */
+        public long getStart()
+        {
+            return _start;
+        }
+
+        /**
+         * @return the end of the period, exclusive, in milliseconds since the epoch
+         */
+        public long getEnd()
+        {
+            return _end;
+        }
+
+        public long getRequests()
+        {
+            return _requests;
+        }
+
+        public long getBytesReceived()
+        {
+            return _received;
+        }
+
+        public long getBytesSent()
+        {
+            return _sent;
+        }
+
+        /**
+         * @return the bytes received plus the bytes sent
+         */
+        public long getBytes()
+        {
+            return _received + _sent;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,%d-%d,requests=%d,received=%d,sent=%d]", getClass().getSimpleName(), hashCode(), _key, _start, _end, _requests, _received, _sent);
+        }
+    }
+}
@@ -0,0 +1,170 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that rejects the requests of the keys that have exceeded
+ * their byte quota for the current period of a {@link UsageAccountingHandler}.</p>
+ * <p>The key of the request is extracted by the key extractor of the
+ * {@link UsageAccountingHandler}, which may be explicitly
+ * {@link #setUsageAccountingHandler(UsageAccountingHandler) set}, or otherwise
+ * is the one found among the descendants of this handler when it is started.</p>
+ * <p>A request is rejected with a {@code 429} response and a {@code Retry-After}
+ * header with the time until the end of the period, when the bytes received
+ * plus the bytes sent for its key in the current period are at least the
+ * {@link #setQuota(String, long) quota} of the key, or the
+ * {@link #setDefaultQuota(long) default quota} if the key has no quota.
+ * Negative quotas are unlimited.</p>
+ * <p>As the usage of a request is only known once it completes, a key may
+ * exceed its quota by the size of the requests in progress when the quota is reached.</p>
+ */
+@ManagedObject("Usage quota handler")
+public class UsageQuotaHandler extends HandlerWrapper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(UsageQuotaHandler.class);
+
+    private final Map<String, Long> _quotas = new ConcurrentHashMap<>();
+    private final LongAdder _rejected = new LongAdder();
+    private UsageAccountingHandler _usageAccountingHandler;
+    private long _defaultQuota = -1;
+
+    public UsageAccountingHandler getUsageAccountingHandler()
+    {
+        return _usageAccountingHandler;
+    }
+
+    /**
+     * @param usageAccountingHandler the handler that accounts the usage enforced by this handler
+     */
+    public void setUsageAccountingHandler(UsageAccountingHandler usageAccountingHandler)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Started");
+        _usageAccountingHandler = usageAccountingHandler;
+    }
+
+    @ManagedAttribute("The bytes quota per period of the keys without a specific quota, negative for unlimited")
+    public long getDefaultQuota()
+    {
+        return _defaultQuota;
+    }
+
+    public void setDefaultQuota(long defaultQuota)
+    {
+        _defaultQuota = defaultQuota;
+    }
+
+    /**
+     * @param key the accounting key
+     * @return the bytes quota per period of the given key, negative for unlimited
+     */
+    public long getQuota(String key)
+    {
+        return _quotas.getOrDefault(key, _defaultQuota);
+    }
+
+    /**
+     * @param key the accounting key
+     * @param quota the bytes quota per period of the given key, negative for unlimited
+     */
+    @ManagedOperation(value = "Sets the bytes quota per period of a key", impact = "ACTION")
+    public void setQuota(String key, long quota)
+    {
+        _quotas.put(key, quota);
+    }
+
+    /**
+     * @param key the accounting key
+     * @return whether the key had a specific quota
+     */
+    @ManagedOperation(value = "Removes the bytes quota of a key", impact = "ACTION")
+    public boolean removeQuota(String key)
+    {
+        return _quotas.remove(key) != null;
+    }
+
+    @ManagedAttribute("The number of requests rejected")
+    public long getRequestsRejected()
+    {
+        return _rejected.sum();
+    }
+
+    @ManagedOperation("Resets the statistics")
+    public void resetStatistics()
+    {
+        _rejected.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_usageAccountingHandler == null)
+            _usageAccountingHandler = getChildHandlerByClass(UsageAccountingHandler.class);
+        if (_usageAccountingHandler == null)
+            throw new IllegalStateException("No UsageAccountingHandler");
+        super.doStart();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.getHttpChannelState().isInitial())
+        {
+            UsageAccountingHandler accounting = _usageAccountingHandler;
+            String key = accounting.getKeyExtractor().apply(baseRequest);
+            UsageAccountingHandler.Usage usage = key == null ? null : accounting.getUsage(key);
+            if (usage != null)
+            {
+                long quota = getQuota(key);
+                UsageAccountingHandler.Rollup period = usage.getCurrentPeriod();
+                if (quota >= 0 && period.getBytes() >= quota)
+                {
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("Rejecting {} for {}, used {}/{} bytes", baseRequest, key, period.getBytes(), quota);
+                    _rejected.increment();
+                    baseRequest.setHandled(true);
+                    long retryAfter = TimeUnit.MILLISECONDS.toSeconds(Math.max(0, period.getEnd() - accounting.currentTimeMillis()) + 999);
+                    response.setHeader(HttpHeader.RETRY_AFTER.asString(), String.valueOf(Math.max(1, retryAfter)));
+                    response.sendError(HttpStatus.TOO_MANY_REQUESTS_429);
+                    return;
+                }
+            }
+        }
+        super.handle(target, baseRequest, request, response);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[defaultQuota=%d,quotas=%d]", getClass().getSimpleName(), hashCode(), _defaultQuota, _quotas.size());
+    }
+}
@@ -0,0 +1,461 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.security.Principal;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.Collections;
+import java.util.Comparator;
+import java.util.HashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.regex.Pattern;
+import javax.net.ssl.SSLSession;
+import javax.servlet.DispatcherType;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.ssl.SslConnection;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.ssl.SniX509ExtendedKeyManager;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.eclipse.jetty.util.ssl.X509;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Handler that dispatches requests to other handlers depending on the requested
+ * host, matched against the host patterns of {@link VirtualHost virtual hosts}.</p>
+ * <p>Host patterns may be:</p>
+ * <ul>
+ * <li>exact host names, for example {@code www.example.com};</li>
+ * <li>wildcard host names, for example {@code *.example.com}, that match a single
+ * DNS label like wildcard certificates do, so {@code a.example.com} but neither
+ * {@code example.com} nor {@code a.b.example.com};</li>
+ * <li>regular expressions, introduced by a leading {@code ^}, that must match
+ * the whole host name, for example {@code ^tenant-[0-9]+\.example\.com$}.</li>
+ * </ul>
+ * <p>Host names are matched case insensitively, and exact patterns take precedence over
+ * wildcard patterns, which take precedence over regular expressions, evaluated in order.
+ * When a virtual host matches, it is set as the request attribute {@link #VIRTUAL_HOST_ATTRIBUTE}
+ * and the request is handled by the virtual host handler.
+ * When no virtual host matches, the request is not handled, so that another handler may handle it.</p>
+ * <p>For TLS requests, the host indicated by the client via SNI during the TLS handshake
+ * is checked against the {@code Host} header according to the {@link SniPolicy}, so that
+ * clients cannot reach a virtual host over a connection established for another virtual host.
+ * Requests that violate the policy are replied with a {@code 421 Misdirected Request} response,
+ * which tells clients to retry the request over a different connection.</p>
+ * <p>This handler is also a {@link SniX509ExtendedKeyManager.SniSelector} that may be set via
+ * {@link SslContextFactory.Server#setSNISelector(SniX509ExtendedKeyManager.SniSelector)}
+ * so that the TLS handshake selects the key material, among the KeyStore aliases, that
+ * is configured for the virtual host via {@link VirtualHost#setCertificateAliases(String...)};
+ * a single wildcard certificate may thus serve a whole group of tenants, while another
+ * group of tenants is served by a different certificate.</p>
+ */
+@ManagedObject("Virtual host handler")
+public class VirtualHostHandler extends AbstractHandlerContainer implements SniX509ExtendedKeyManager.SniSelector
+{
+    public static final String VIRTUAL_HOST_ATTRIBUTE = "org.eclipse.jetty.server.handler.VirtualHostHandler.virtualHost";
+    private static final Logger LOG = LoggerFactory.getLogger(VirtualHostHandler.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final List<VirtualHost> _virtualHosts = new CopyOnWriteArrayList<>();
+    private volatile Index _index = new Index(List.of());
+    private SniPolicy _sniPolicy = SniPolicy.VIRTUAL_HOST;
+
+    /**
+     * @return the policy to check the TLS SNI host against the {@code Host} header
+     */
+    @ManagedAttribute("The policy to check the TLS SNI host against the Host header")
+    public SniPolicy getSniPolicy()
+    {
+        return _sniPolicy;
+    }
+
+    /**
+     * @param sniPolicy the policy to check the TLS SNI host against the {@code Host} header
+     */
+    public void setSniPolicy(SniPolicy sniPolicy)
+    {
+        _sniPolicy = Objects.requireNonNull(sniPolicy);
+    }
+
+    /**
+     * <p>Adds a virtual host.</p>
+     *
+     * @param handler the handler of the requests for the virtual host
+     * @param hostPatterns the host patterns of the virtual host
+     * @return the virtual host
+     * @throws IllegalArgumentException if a pattern is invalid, or if an exact
+     * or wildcard pattern is already used by another virtual host
+     */
+    public VirtualHost addVirtualHost(Handler handler, String... hostPatterns)
+    {
+        VirtualHost virtualHost = new VirtualHost(Objects.requireNonNull(handler), List.of(hostPatterns));
+        try (AutoLock l = _lock.lock())
+        {
+            List<VirtualHost> virtualHosts = new ArrayList<>(_virtualHosts);
+            virtualHosts.add(virtualHost);
+            _index = new Index(virtualHosts);
+            _virtualHosts.add(virtualHost);
+        }
+        if (getServer() != null)
+            handler.setServer(getServer());
+        addBean(handler);
+        return virtualHost;
+    }
+
+    /**
+     * @param virtualHost the virtual host to remove
+     * @return whether the virtual host was removed
+     */
+    public boolean removeVirtualHost(VirtualHost virtualHost)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            if (!_virtualHosts.remove(virtualHost))
+                return false;
+            _index = new Index(_virtualHosts);
+        }
+        if (_virtualHosts.stream().noneMatch(v -> v.getHandler() == virtualHost.getHandler()))
+            removeBean(virtualHost.getHandler());
+        return true;
+    }
+
+    /**
+     * @return the virtual hosts, in the order they have been added
+     */
+    public List<VirtualHost> getVirtualHosts()
+    {
+        return Collections.unmodifiableList(_virtualHosts);
+    }
+
+    /**
+     * @param host the host name
+     * @return the virtual host matching the given host name, or {@code null} if no virtual host matches
+     */
+    public VirtualHost match(String host)
+    {
+        String normalized = normalize(host);
+        if (normalized == null)
+            return null;
+        return _index.match(normalized);
+    }
+
+    @Override
+    public Handler[] getHandlers()
+    {
+        return _virtualHosts.stream().map(VirtualHost::getHandler).distinct().toArray(Handler[]::new);
+    }
+
+    @Override
+    protected void expandChildren(List<Handler> list, Class<?> byClass)
+    {
+        for (Handler handler : getHandlers())
+        {
+            expandHandler(handler, list, byClass);
+        }
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.isHandled() || !isStarted())
+            return;
+
+        String host = request.getServerName();
+        VirtualHost virtualHost = match(host);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Host {} matched {}", host, virtualHost);
+        if (virtualHost == null)
+            return;
+
+        if (baseRequest.getDispatcherType() == DispatcherType.REQUEST && !isSniValid(baseRequest, host, virtualHost))
+        {
+            baseRequest.setHandled(true);
+            response.sendError(HttpStatus.MISDIRECTED_REQUEST_421);
+            return;
+        }
+
+        baseRequest.setAttribute(VIRTUAL_HOST_ATTRIBUTE, virtualHost);
+        virtualHost.getHandler().handle(target, baseRequest, request, response);
+    }
+
+    private boolean isSniValid(Request baseRequest, String host, VirtualHost virtualHost)
+    {
+        String sniHost = getSniHost(baseRequest);
+        // Not a TLS request, or the client did not send the SNI.
+        if (sniHost == null)
+            return true;
+
+        boolean valid;
+        switch (getSniPolicy())
+        {
+            case IGNORE:
+                valid = true;
+                break;
+            case VIRTUAL_HOST:
+                valid = match(sniHost) == virtualHost;
+                break;
+            case EXACT:
+                valid = Objects.equals(normalize(sniHost), normalize(host));
+                break;
+            default:
+                throw new IllegalStateException();
+        }
+        if (!valid && LOG.isDebugEnabled())
+            LOG.debug("Misdirected request, Host={}, SNI={} for {}", host, sniHost, baseRequest);
+        return valid;
+    }
+
+    /**
+     * <p>Returns the host indicated by the client via SNI during the TLS handshake.</p>
+     *
+     * @param baseRequest the request
+     * @return the SNI host, or {@code null} if the request is not a TLS request or the client did not send the SNI
+     */
+    protected String getSniHost(Request baseRequest)
+    {
+        EndPoint endPoint = baseRequest.getHttpChannel().getEndPoint();
+        if (endPoint instanceof SslConnection.DecryptedEndPoint)
+        {
+            SSLSession sslSession = ((SslConnection.DecryptedEndPoint)endPoint).getSslConnection().getSSLEngine().getSession();
+            return (String)sslSession.getValue(SslContextFactory.Server.SNI_HOST);
+        }
+        return null;
+    }
+
+    /**
+     * <p>Selects the certificate configured for the virtual host matching the SNI host.</p>
+     * <p>The certificates of the virtual host that match the SNI host are preferred,
+     * non-wildcard ones first; when none matches, for example because the virtual host uses
+     * regular expressions, the first certificate configured for the virtual host is selected.
+     * When no virtual host matches the SNI host, or the virtual host has no certificates
+     * configured, the certificate is selected among all the certificates matching the SNI host,
+     * or the selection is delegated if none matches.</p>
+     */
+    @Override
+    public String sniSelect(String keyType, Principal[] issuers, SSLSession session, String sniHost, Collection<X509> certificates)
+    {
+        if (sniHost == null)
+            return SniX509ExtendedKeyManager.SniSelector.DELEGATE;
+
+        VirtualHost virtualHost = match(sniHost);
+        List<X509> candidates = new ArrayList<>();
+        boolean configured = virtualHost != null && !virtualHost.getCertificateAliases().isEmpty();
+        if (configured)
+        {
+            for (String alias : virtualHost.getCertificateAliases())
+            {
+                certificates.stream()
+                    .filter(x509 -> alias.equals(x509.getAlias()))
+                    .findFirst()
+                    .ifPresent(candidates::add);
+            }
+        }
+        else
+        {
+            candidates.addAll(certificates);
+        }
+
+        String alias = candidates.stream()
+            .filter(x509 -> x509.matches(sniHost))
+            .min(Comparator.comparingInt(x509 -> x509.getWilds().size()))
+            .map(X509::getAlias)
+            .orElse(null);
+        if (alias == null)
+        {
+            if (configured)
+            {
+                // If there are no candidates for this keyType,
+                // we will likely be called again with a different keyType.
+                alias = candidates.isEmpty() ? null : candidates.get(0).getAlias();
+            }
+            else
+            {
+                alias = SniX509ExtendedKeyManager.SniSelector.DELEGATE;
+            }
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Selected alias={} for keyType={}, sni={}, virtualHost={}", alias, keyType, sniHost, virtualHost);
+        return alias;
+    }
+
+    private static String normalize(String host)
+    {
+        if (StringUtil.isEmpty(host))
+            return null;
+        host = StringUtil.asciiToLowerCase(host);
+        // Fully qualified host names may have a trailing dot.
+        if (host.endsWith("."))
+            host = host.substring(0, host.length() - 1);
+        return host;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{%s,virtualHosts=%d}", getClass().getSimpleName(), hashCode(), getState(), _virtualHosts.size());
+    }
+
+    /**
+     * <p>The policies to check the host indicated by the client via SNI
+     * during the TLS handshake against the {@code Host} header.</p>
+     */
+    public enum SniPolicy
+    {
+        /**
+         * The SNI host is not checked.
+         */
+        IGNORE,
+        /**
+         * The SNI host must match the same virtual host that the {@code Host} header matches,
+         * so that, for example, HTTP/2 connections may be reused for all the hosts of a
+         * virtual host served by the same wildcard certificate.
+         */
+        VIRTUAL_HOST,
+        /**
+         * The SNI host must be the same as the {@code Host} header.
+         */
+        EXACT
+    }
+
+    /**
+     * <p>A virtual host, mapping a group of host patterns to a handler.</p>
+     */
+    public static class VirtualHost
+    {
+        private final Handler _handler;
+        private final List<String> _hostPatterns;
+        private volatile Set<String> _certificateAliases = Set.of();
+
+        private VirtualHost(Handler handler, List<String> hostPatterns)
+        {
+            if (hostPatterns.isEmpty())
+                throw new IllegalArgumentException("No host patterns");
+            _handler = handler;
+            _hostPatterns = hostPatterns;
+        }
+
+        public Handler getHandler()
+        {
+            return _handler;
+        }
+
+        public List<String> getHostPatterns()
+        {
+            return _hostPatterns;
+        }
+
+        /**
+         * @return the KeyStore aliases of the certificates of this virtual host
+         */
+        public Set<String> getCertificateAliases()
+        {
+            return _certificateAliases;
+        }
+
+        /**
+         * <p>Sets the KeyStore aliases of the certificates to select during the TLS
+         * handshake when the SNI host matches this virtual host.</p>
+         * <p>Multiple aliases may be configured, for example for certificates having
+         * different key types, and are selected in the order they are specified.</p>
+         *
+         * @param aliases the KeyStore aliases of the certificates of this virtual host
+         * @see VirtualHostHandler#sniSelect(String, Principal[], SSLSession, String, Collection)
+         */
+        public void setCertificateAliases(String... aliases)
+        {
+            _certificateAliases = Collections.unmodifiableSet(new LinkedHashSet<>(List.of(aliases)));
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s -> %s", _hostPatterns, _handler);
+        }
+    }
+
+    private static class Index
+    {
+        private final Map<String, VirtualHost> _exact = new HashMap<>();
+        private final Map<String, VirtualHost> _wilds = new HashMap<>();
+        private final List<Map.Entry<Pattern, VirtualHost>> _regexes = new ArrayList<>();
+
+        private Index(List<VirtualHost> virtualHosts)
+        {
+            for (VirtualHost virtualHost : virtualHosts)
+            {
+                for (String hostPattern : virtualHost.getHostPatterns())
+                {
+                    if (hostPattern.startsWith("^"))
+                    {
+                        _regexes.add(Map.entry(Pattern.compile(hostPattern, Pattern.CASE_INSENSITIVE), virtualHost));
+                        continue;
+                    }
+
+                    String host = normalize(hostPattern);
+                    Map<String, VirtualHost> map = _exact;
+                    if (host != null && host.startsWith("*."))
+                    {
+                        host = host.substring(2);
+                        map = _wilds;
+                    }
+                    if (host == null || host.isEmpty() || host.indexOf('*') >= 0)
+                        throw new IllegalArgumentException("Invalid host pattern '" + hostPattern + "'");
+                    VirtualHost existing = map.putIfAbsent(host, virtualHost);
+                    if (existing != null && existing != virtualHost)
+                        throw new IllegalArgumentException("Duplicate host pattern '" + hostPattern + "' for " + existing);
+                }
+            }
+        }
+
+        private VirtualHost match(String host)
+        {
+            VirtualHost virtualHost = _exact.get(host);
+            if (virtualHost != null)
+                return virtualHost;
+
+            int dot = host.indexOf('.');
+            if (dot > 0)
+            {
+                virtualHost = _wilds.get(host.substring(dot + 1));
+                if (virtualHost != null)
+                    return virtualHost;
+            }
+
+            for (Map.Entry<Pattern, VirtualHost> entry : _regexes)
+            {
+                if (entry.getKey().matcher(host).matches())
+                    return entry.getValue();
+            }
+            return null;
+        }
+    }
+}
@@ -0,0 +1,129 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.Executor;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.AsyncContext;
+import javax.servlet.DispatcherType;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.HttpChannelState;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.VirtualThreads;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that forces the handling of requests in virtual threads,
+ * typically used to wrap handlers that are known to block.</p>
+ * <p>When a request arrives in a platform thread, it is suspended and then redispatched
+ * to the wrapped handler in a virtual thread, so that the platform thread is returned
+ * to the thread pool; the redispatch is presented to the wrapped handler as the original
+ * {@link DispatcherType#REQUEST REQUEST} dispatch, and further async dispatches of the
+ * request are also executed in virtual threads.</p>
+ * <p>Since the wrapped handler only sees the request after the redispatch, this handler
+ * must be placed outside of the {@link ContextHandler}s that it applies to, for example
+ * as the server handler, or between a {@link ContextHandlerCollection} and a context.</p>
+ * <p>The virtual threads executor is the one configured on this handler, or otherwise
+ * the one of the server thread pool; if no virtual threads executor is available,
+ * for example because the runtime does not support virtual threads, requests are
+ * handled by the wrapped handler without redispatch.</p>
+ */
+@ManagedObject("Handler that forces virtual threads for blocking handlers")
+public class VirtualThreadsHandler extends HandlerWrapper implements VirtualThreads.Configurable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(VirtualThreadsHandler.class);
+
+    private final String _attribute = VirtualThreadsHandler.class.getName() + "@" + Integer.toHexString(hashCode());
+    private final LongAdder _redispatched = new LongAdder();
+    private Executor _virtualThreadsExecutor;
+
+    @Override
+    public Executor getVirtualThreadsExecutor()
+    {
+        if (_virtualThreadsExecutor != null)
+            return _virtualThreadsExecutor;
+        return getServer() == null ? null : VirtualThreads.getVirtualThreadsExecutor(getServer().getThreadPool());
+    }
+
+    @Override
+    public void setVirtualThreadsExecutor(Executor executor)
+    {
+        VirtualThreads.Configurable.super.setVirtualThreadsExecutor(executor);
+        _virtualThreadsExecutor = executor;
+    }
+
+    @ManagedAttribute("The number of requests redispatched to virtual threads")
+    public long getRequestsRedispatched()
+    {
+        return _redispatched.sum();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.getAttribute(_attribute) != null)
+        {
+            // The redispatch of the original request dispatch.
+            baseRequest.removeAttribute(_attribute);
+            baseRequest.setDispatcherType(DispatcherType.REQUEST);
+            try
+            {
+                super.handle(target, baseRequest, request, response);
+            }
+            finally
+            {
+                baseRequest.setDispatcherType(DispatcherType.ASYNC);
+            }
+            return;
+        }
+
+        Executor executor = baseRequest.getDispatcherType() == DispatcherType.REQUEST ? getVirtualThreadsExecutor() : null;
+        if (executor == null || isVirtualThread())
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Redispatching to virtual thread {}", baseRequest);
+        _redispatched.increment();
+        baseRequest.setAttribute(_attribute, Boolean.TRUE);
+        baseRequest.getHttpChannel().setDispatchExecutor(executor);
+        HttpChannelState state = baseRequest.getHttpChannelState();
+        AsyncContext asyncContext = baseRequest.startAsync();
+        asyncContext.setTimeout(0);
+        executor.execute(() ->
+        {
+            // Wait for the platform thread to exit the handling, otherwise it would
+            // process the dispatch itself, rather than executing it in a virtual thread.
+            while (state.getState() == HttpChannelState.State.HANDLING)
+            {
+                Thread.yield();
+            }
+            asyncContext.dispatch();
+        });
+    }
+
+    private static boolean isVirtualThread()
+    {
+        return VirtualThreads.areSupported() && VirtualThreads.isVirtualThread();
+    }
+}
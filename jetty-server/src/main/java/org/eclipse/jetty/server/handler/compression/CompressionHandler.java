@@ -0,0 +1,342 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import javax.servlet.ServletContext;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.CompressedContentFormat;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.server.handler.gzip.GzipHttpOutputInterceptor;
+import org.eclipse.jetty.util.AsciiLowerCaseSet;
+import org.eclipse.jetty.util.IncludeExclude;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A Handler that compresses responses with the content coding negotiated
+ * from the request {@code Accept-Encoding} header.</p>
+ * <p>The available content codings are the {@link ContentCodec}s discovered by
+ * the {@link java.util.ServiceLoader}: {@code gzip} is always available, while
+ * other codecs such as {@code zstd} become available by adding their jars to
+ * the server class-path or module-path.
+ * The {@link #setEncodings(String...) encodings} configure which codecs are
+ * used, in order of server preference: the client preference expressed by the
+ * {@code q} values of the {@code Accept-Encoding} header always wins, while the
+ * server preference breaks ties.</p>
+ * <p>Responses are compressed only for the {@link #setIncludedMethods(String...) included methods}
+ * and for content types that are allowed by the {@link #setIncludedMimeTypes(String...) included}
+ * and {@link #setExcludedMimeTypes(String...) excluded} mime types; by default, images, audio,
+ * video and already compressed archive types are excluded.</p>
+ * <p>Unlike {@link org.eclipse.jetty.server.handler.gzip.GzipHandler}, this handler does
+ * not inflate request content; see {@link DecompressionHandler} for that.</p>
+ */
+@ManagedObject("Compression Handler")
+public class CompressionHandler extends HandlerWrapper
+{
+    public static final String ETAGS_ATTRIBUTE = CompressionHandler.class.getName() + ".etags";
+    public static final int DEFAULT_MIN_COMPRESS_SIZE = 32;
+    private static final Logger LOG = LoggerFactory.getLogger(CompressionHandler.class);
+
+    private final List<ContentCodec> _codecs = new ArrayList<>();
+    private final Map<String, Integer> _levels = new HashMap<>();
+    private final IncludeExclude<String> _methods = new IncludeExclude<>();
+    private final IncludeExclude<String> _mimeTypes = new IncludeExclude<>(AsciiLowerCaseSet.class);
+    private int _minCompressSize = DEFAULT_MIN_COMPRESS_SIZE;
+
+    public CompressionHandler()
+    {
+        for (ContentCodec codec : ContentCodec.getContentCodecs())
+        {
+            if (codec.isEncodingSupported())
+                _codecs.add(codec);
+        }
+        _methods.include(HttpMethod.GET.asString());
+        for (String type : MimeTypes.getKnownMimeTypes())
+        {
+            if ((type.startsWith("image/") && !"image/svg+xml".equals(type)) ||
+                type.startsWith("audio/") ||
+                type.startsWith("video/"))
+                _mimeTypes.exclude(type);
+        }
+        _mimeTypes.exclude("application/compress");
+        _mimeTypes.exclude("application/zip");
+        _mimeTypes.exclude("application/gzip");
+        _mimeTypes.exclude("application/bzip2");
+        _mimeTypes.exclude("application/brotli");
+        _mimeTypes.exclude("application/zstd");
+        _mimeTypes.exclude("application/x-xz");
+        _mimeTypes.exclude("application/x-rar-compressed");
+        _mimeTypes.exclude("text/event-stream");
+    }
+
+    /**
+     * @return the content codings used to compress responses, in order of server preference
+     */
+    @ManagedAttribute("The content codings used to compress responses, in order of server preference")
+    public String[] getEncodings()
+    {
+        return _codecs.stream().map(ContentCodec::getEncoding).toArray(String[]::new);
+    }
+
+    /**
+     * @param encodings the content codings used to compress responses, in order of server preference
+     * @throws IllegalArgumentException if a content coding is not available or does not support encoding
+     */
+    public void setEncodings(String... encodings)
+    {
+        List<ContentCodec> codecs = new ArrayList<>();
+        for (String encoding : encodings)
+        {
+            ContentCodec codec = ContentCodec.getContentCodec(encoding.trim());
+            if (codec == null || !codec.isEncodingSupported())
+                throw new IllegalArgumentException("Unsupported content coding " + encoding);
+            codecs.add(codec);
+        }
+        _codecs.clear();
+        _codecs.addAll(codecs);
+    }
+
+    /**
+     * @param csv a comma separated list of content codings, in order of server preference
+     * @see #setEncodings(String...)
+     */
+    public void setEncodingList(String csv)
+    {
+        setEncodings(StringUtil.csvSplit(csv));
+    }
+
+    /**
+     * @param encoding the content coding
+     * @return the compression level used for the given content coding
+     */
+    public int getLevel(String encoding)
+    {
+        Integer level = _levels.get(encoding);
+        if (level != null)
+            return level;
+        ContentCodec codec = ContentCodec.getContentCodec(encoding);
+        return codec == null ? -1 : codec.getDefaultLevel();
+    }
+
+    /**
+     * @param encoding the content coding
+     * @param level the compression level used for the given content coding, or a negative value for the codec default
+     */
+    public void setLevel(String encoding, int level)
+    {
+        if (level < 0)
+            _levels.remove(encoding);
+        else
+            _levels.put(encoding, level);
+    }
+
+    @ManagedAttribute("The minimum content length to compress responses")
+    public int getMinCompressSize()
+    {
+        return _minCompressSize;
+    }
+
+    /**
+     * @param minCompressSize the minimum content length to compress responses,
+     * when the content length is known
+     */
+    public void setMinCompressSize(int minCompressSize)
+    {
+        _minCompressSize = minCompressSize;
+    }
+
+    @ManagedAttribute("The included HTTP methods")
+    public String[] getIncludedMethods()
+    {
+        return _methods.getIncluded().toArray(new String[0]);
+    }
+
+    public void setIncludedMethods(String... methods)
+    {
+        _methods.getIncluded().clear();
+        _methods.include(methods);
+    }
+
+    public void setIncludedMethodList(String csv)
+    {
+        setIncludedMethods(StringUtil.csvSplit(csv));
+    }
+
+    @ManagedAttribute("The included mime types")
+    public String[] getIncludedMimeTypes()
+    {
+        return _mimeTypes.getIncluded().toArray(new String[0]);
+    }
+
+    /**
+     * <p>Sets the allowlist of mime types to compress; when not empty,
+     * only the included mime types are compressed.</p>
+     *
+     * @param types the mime types to compress
+     */
+    public void setIncludedMimeTypes(String... types)
+    {
+        _mimeTypes.getIncluded().clear();
+        _mimeTypes.include(types);
+    }
+
+    public void setIncludedMimeTypesList(String csv)
+    {
+        setIncludedMimeTypes(StringUtil.csvSplit(csv));
+    }
+
+    @ManagedAttribute("The excluded mime types")
+    public String[] getExcludedMimeTypes()
+    {
+        return _mimeTypes.getExcluded().toArray(new String[0]);
+    }
+
+    /**
+     * @param types the mime types to never compress, replacing the default excluded mime types
+     */
+    public void setExcludedMimeTypes(String... types)
+    {
+        _mimeTypes.getExcluded().clear();
+        _mimeTypes.exclude(types);
+    }
+
+    public void setExcludedMimeTypesList(String csv)
+    {
+        setExcludedMimeTypes(StringUtil.csvSplit(csv));
+    }
+
+    /**
+     * @param mimeType the mime type, without parameters
+     * @return whether content of the given mime type may be compressed
+     */
+    public boolean isMimeTypeCompressible(String mimeType)
+    {
+        return _mimeTypes.test(mimeType);
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.isHandled() || response.isCommitted())
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        // Are we already compressing?
+        HttpOutput out = baseRequest.getResponse().getHttpOutput();
+        for (HttpOutput.Interceptor interceptor = out.getInterceptor(); interceptor != null; interceptor = interceptor.getNextInterceptor())
+        {
+            if (interceptor instanceof CompressionHttpOutputInterceptor || interceptor instanceof GzipHttpOutputInterceptor)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("{} already intercepting {}", this, request);
+                super.handle(target, baseRequest, request, response);
+                return;
+            }
+        }
+
+        // If not a supported method, no Vary because no matter what client, this URI is always excluded.
+        if (!_methods.test(baseRequest.getMethod()))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} excluded by method {}", this, request);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        // Exclude non compressible mime-types known from URI extension.
+        ServletContext context = baseRequest.getServletContext();
+        String path = baseRequest.getPathInContext();
+        String mimeType = context == null ? MimeTypes.getDefaultMimeByExtension(path) : context.getMimeType(path);
+        if (mimeType != null && !isMimeTypeCompressible(HttpField.valueParameters(mimeType, null)))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} excluded by path suffix mime type {}", this, request);
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        ContentCodec codec = ContentCodec.negotiate(baseRequest.getHttpFields().getValuesList(HttpHeader.ACCEPT_ENCODING), _codecs);
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} negotiated {} for {}", this, codec, request);
+        if (codec != null)
+            stripEtagSuffixes(baseRequest, codec);
+
+        HttpOutput.Interceptor origInterceptor = out.getInterceptor();
+        try
+        {
+            out.setInterceptor(new CompressionHttpOutputInterceptor(this, codec, baseRequest.getHttpChannel(), origInterceptor));
+            super.handle(target, baseRequest, request, response);
+        }
+        finally
+        {
+            // Reset the interceptor if the request was not handled.
+            if (!baseRequest.isHandled() && !baseRequest.isAsyncStarted())
+                out.setInterceptor(origInterceptor);
+        }
+    }
+
+    private void stripEtagSuffixes(Request baseRequest, ContentCodec codec)
+    {
+        HttpFields httpFields = baseRequest.getHttpFields();
+        if (!httpFields.contains(HttpHeader.IF_MATCH) && !httpFields.contains(HttpHeader.IF_NONE_MATCH))
+            return;
+
+        CompressedContentFormat format = new CompressedContentFormat(codec.getEncoding(), "." + codec.getEncoding());
+        HttpFields.Mutable newFields = HttpFields.build(httpFields.size());
+        for (HttpField field : httpFields)
+        {
+            if (field.getHeader() == HttpHeader.IF_MATCH || field.getHeader() == HttpHeader.IF_NONE_MATCH)
+            {
+                String etags = field.getValue();
+                String etagsNoSuffix = format.stripSuffixes(etags);
+                if (!etagsNoSuffix.equals(etags))
+                {
+                    newFields.add(new HttpField(field.getHeader(), etagsNoSuffix));
+                    baseRequest.setAttribute(ETAGS_ATTRIBUTE, etags);
+                    continue;
+                }
+            }
+            newFields.add(field);
+        }
+        baseRequest.setHttpFields(newFields);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), Arrays.toString(getEncodings()));
+    }
+}
@@ -0,0 +1,239 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.io.ByteArrayOutputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.http.CompressedContentFormat;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.PreEncodedHttpField;
+import org.eclipse.jetty.server.HttpChannel;
+import org.eclipse.jetty.server.HttpOutput;
+import org.eclipse.jetty.server.Response;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link HttpOutput.Interceptor} that encodes the response content
+ * with the {@link ContentCodec} negotiated by the {@link CompressionHandler}.</p>
+ */
+public class CompressionHttpOutputInterceptor implements HttpOutput.Interceptor
+{
+    private static final Logger LOG = LoggerFactory.getLogger(CompressionHttpOutputInterceptor.class);
+
+    public static final HttpField VARY_ACCEPT_ENCODING = new PreEncodedHttpField(HttpHeader.VARY, HttpHeader.ACCEPT_ENCODING.asString());
+
+    private enum State
+    {
+        MIGHT_COMPRESS, NOT_COMPRESSING, COMPRESSING, FINISHED
+    }
+
+    private final CompressionHandler _handler;
+    private final ContentCodec _codec;
+    private final HttpChannel _channel;
+    private final HttpOutput.Interceptor _interceptor;
+    private final Sink _sink = new Sink();
+    private State _state = State.MIGHT_COMPRESS;
+    private OutputStream _encoder;
+
+    /**
+     * @param handler the handler that installed this interceptor
+     * @param codec the negotiated codec, or {@code null} if the client does not accept any of the available codecs
+     * @param channel the channel of the response
+     * @param next the next interceptor
+     */
+    public CompressionHttpOutputInterceptor(CompressionHandler handler, ContentCodec codec, HttpChannel channel, HttpOutput.Interceptor next)
+    {
+        _handler = handler;
+        _codec = codec;
+        _channel = channel;
+        _interceptor = next;
+    }
+
+    /**
+     * @return the negotiated codec, or {@code null}
+     */
+    public ContentCodec getContentCodec()
+    {
+        return _codec;
+    }
+
+    @Override
+    public HttpOutput.Interceptor getNextInterceptor()
+    {
+        return _interceptor;
+    }
+
+    @Override
+    public void write(ByteBuffer content, boolean complete, Callback callback)
+    {
+        switch (_state)
+        {
+            case MIGHT_COMPRESS:
+                commit(content, complete, callback);
+                break;
+            case NOT_COMPRESSING:
+                _interceptor.write(content, complete, callback);
+                break;
+            case COMPRESSING:
+                compress(content, complete, callback);
+                break;
+            default:
+                callback.failed(new IllegalStateException("state=" + _state));
+                break;
+        }
+    }
+
+    private void commit(ByteBuffer content, boolean complete, Callback callback)
+    {
+        Response response = _channel.getResponse();
+        HttpFields.Mutable fields = response.getHttpFields();
+
+        // Are we excluding because of status?
+        int sc = response.getStatus();
+        if (sc > 0 && (sc < 200 || sc == 204 || sc == 205 || sc >= 300))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} exclude by status {}", this, sc);
+            _state = State.NOT_COMPRESSING;
+            if (sc == HttpStatus.NOT_MODIFIED_304 && _codec != null)
+            {
+                String requestEtags = (String)_channel.getRequest().getAttribute(CompressionHandler.ETAGS_ATTRIBUTE);
+                String responseEtag = fields.get(HttpHeader.ETAG);
+                if (requestEtags != null && responseEtag != null)
+                {
+                    String etag = format().etag(responseEtag);
+                    if (requestEtags.contains(etag))
+                        fields.put(HttpHeader.ETAG, etag);
+                    fields.ensureField(VARY_ACCEPT_ENCODING);
+                }
+            }
+            _interceptor.write(content, complete, callback);
+            return;
+        }
+
+        // Are we excluding because of mime-type?
+        String ct = response.getContentType();
+        if (ct != null && !_handler.isMimeTypeCompressible(HttpField.valueParameters(ct, null)))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} exclude by mimeType {}", this, ct);
+            _state = State.NOT_COMPRESSING;
+            _interceptor.write(content, complete, callback);
+            return;
+        }
+
+        // Has the Content-Encoding header already been set?
+        String ce = fields.get(HttpHeader.CONTENT_ENCODING);
+        if (ce != null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} exclude by content-encoding {}", this, ce);
+            _state = State.NOT_COMPRESSING;
+            _interceptor.write(content, complete, callback);
+            return;
+        }
+
+        // The response varies depending on the Accept-Encoding header.
+        fields.ensureField(VARY_ACCEPT_ENCODING);
+
+        long contentLength = response.getContentLength();
+        if (contentLength < 0 && complete)
+            contentLength = content.remaining();
+        if (_codec == null || (contentLength >= 0 && contentLength < _handler.getMinCompressSize()))
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} exclude codec={} contentLength={}", this, _codec, contentLength);
+            _state = State.NOT_COMPRESSING;
+            _interceptor.write(content, complete, callback);
+            return;
+        }
+
+        try
+        {
+            _encoder = _codec.newEncoder(_sink, _handler.getLevel(_codec.getEncoding()));
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+            return;
+        }
+
+        fields.put(HttpHeader.CONTENT_ENCODING, _codec.getEncoding());
+        response.setContentLength(-1);
+        String etag = fields.get(HttpHeader.ETAG);
+        if (etag != null)
+            fields.put(HttpHeader.ETAG, format().etag(etag));
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} compressing with {}", this, _codec);
+        _state = State.COMPRESSING;
+        compress(content, complete, callback);
+    }
+
+    private void compress(ByteBuffer content, boolean complete, Callback callback)
+    {
+        try
+        {
+            if (content.hasRemaining())
+                BufferUtil.writeTo(content, _encoder);
+            else if (!complete)
+                _encoder.flush();
+            if (complete)
+            {
+                _state = State.FINISHED;
+                _encoder.close();
+            }
+            _interceptor.write(_sink.take(), complete, callback);
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+        }
+    }
+
+    private CompressedContentFormat format()
+    {
+        return new CompressedContentFormat(_codec.getEncoding(), "." + _codec.getEncoding());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), _state, _codec);
+    }
+
+    /**
+     * <p>Accumulates the encoded bytes until they are written to the next interceptor.</p>
+     */
+    private static class Sink extends ByteArrayOutputStream
+    {
+        private ByteBuffer take()
+        {
+            if (count == 0)
+                return BufferUtil.EMPTY_BUFFER;
+            ByteBuffer result = ByteBuffer.wrap(toByteArray());
+            reset();
+            return result;
+        }
+    }
+}
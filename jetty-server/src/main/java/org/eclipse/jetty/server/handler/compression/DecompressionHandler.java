@@ -0,0 +1,240 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.Function;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A Handler that decodes request content compressed with the content codings
+ * specified by the request {@code Content-Encoding} header.</p>
+ * <p>The available content codings are {@code deflate} and the {@link ContentCodec}s
+ * discovered by the {@link java.util.ServiceLoader} that support decoding: {@code gzip}
+ * is always available, while other codecs such as {@code zstd} become available by
+ * adding their jars to the server class-path or module-path.</p>
+ * <p>Requests with a content coding that is not {@link #setEncodings(String...) enabled}
+ * are rejected with a {@code 415} status code and an {@code Accept-Encoding} response
+ * header listing the enabled content codings.</p>
+ * <p>To protect against decompression bombs, the request content fails with a
+ * {@code 413} status code when the decoded content exceeds the
+ * {@link #setMaxDecompressedSize(long) max decompressed size}.</p>
+ * <p>The decoded request has no {@code Content-Encoding} and {@code Content-Length}
+ * headers; their original values are available in the {@code X-Content-Encoding}
+ * and {@code X-Content-Length} headers.</p>
+ */
+@ManagedObject("Decompression Handler")
+public class DecompressionHandler extends HandlerWrapper
+{
+    public static final long DEFAULT_MAX_DECOMPRESSED_SIZE = 64 * 1024 * 1024;
+    private static final Logger LOG = LoggerFactory.getLogger(DecompressionHandler.class);
+    private static final String DEFLATE = "deflate";
+    private static final String IDENTITY = "identity";
+
+    private final Map<String, Function<ByteBufferPool, ContentCodec.Decoder>> _decoders = new LinkedHashMap<>();
+    private final LongAdder _decompressedRequests = new LongAdder();
+    private final LongAdder _unsupportedRequests = new LongAdder();
+    private final LongAdder _oversizedRequests = new LongAdder();
+    private long _maxDecompressedSize = DEFAULT_MAX_DECOMPRESSED_SIZE;
+
+    public DecompressionHandler()
+    {
+        for (ContentCodec codec : ContentCodec.getContentCodecs())
+        {
+            if (codec.isDecodingSupported())
+                _decoders.put(codec.getEncoding().toLowerCase(Locale.ENGLISH), codec::newDecoder);
+        }
+        _decoders.putIfAbsent(DEFLATE, pool -> new DeflateContentDecoder());
+    }
+
+    /**
+     * @return the content codings decoded by this handler
+     */
+    @ManagedAttribute("The content codings decoded by this handler")
+    public String[] getEncodings()
+    {
+        return _decoders.keySet().toArray(new String[0]);
+    }
+
+    /**
+     * @param encodings the content codings decoded by this handler
+     * @throws IllegalArgumentException if a content coding is not available or does not support decoding
+     */
+    public void setEncodings(String... encodings)
+    {
+        Map<String, Function<ByteBufferPool, ContentCodec.Decoder>> decoders = new LinkedHashMap<>();
+        for (String encoding : encodings)
+        {
+            String coding = encoding.trim().toLowerCase(Locale.ENGLISH);
+            ContentCodec codec = ContentCodec.getContentCodec(coding);
+            if (codec != null && codec.isDecodingSupported())
+                decoders.put(coding, codec::newDecoder);
+            else if (DEFLATE.equals(coding))
+                decoders.put(coding, pool -> new DeflateContentDecoder());
+            else
+                throw new IllegalArgumentException("Unsupported content coding " + encoding);
+        }
+        _decoders.clear();
+        _decoders.putAll(decoders);
+    }
+
+    /**
+     * @param csv a comma separated list of content codings
+     * @see #setEncodings(String...)
+     */
+    public void setEncodingList(String csv)
+    {
+        setEncodings(StringUtil.csvSplit(csv));
+    }
+
+    @ManagedAttribute("The max decompressed size of request content, or -1 for no limit")
+    public long getMaxDecompressedSize()
+    {
+        return _maxDecompressedSize;
+    }
+
+    /**
+     * @param maxDecompressedSize the max decompressed size of request content, or -1 for no limit
+     */
+    public void setMaxDecompressedSize(long maxDecompressedSize)
+    {
+        _maxDecompressedSize = maxDecompressedSize;
+    }
+
+    @ManagedAttribute("The number of requests with decompressed content")
+    public long getDecompressedRequests()
+    {
+        return _decompressedRequests.longValue();
+    }
+
+    @ManagedAttribute("The number of requests rejected because of an unsupported content coding")
+    public long getUnsupportedRequests()
+    {
+        return _unsupportedRequests.longValue();
+    }
+
+    @ManagedAttribute("The number of requests failed because the max decompressed size was exceeded")
+    public long getOversizedRequests()
+    {
+        return _oversizedRequests.longValue();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _decompressedRequests.reset();
+        _unsupportedRequests.reset();
+        _oversizedRequests.reset();
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HttpFields httpFields = baseRequest.getHttpFields();
+        // Only the initial dispatch decodes the request content.
+        if (!baseRequest.getHttpChannelState().isInitial() || !httpFields.contains(HttpHeader.CONTENT_ENCODING))
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        List<String> codings = httpFields.getCSV(HttpHeader.CONTENT_ENCODING, false);
+        List<ContentCodec.Decoder> decoders = new ArrayList<>(codings.size());
+        ByteBufferPool byteBufferPool = baseRequest.getHttpChannel().getByteBufferPool();
+        // Decode in the reverse order the content codings were applied.
+        for (int i = codings.size() - 1; i >= 0; --i)
+        {
+            String coding = codings.get(i).trim().toLowerCase(Locale.ENGLISH);
+            if (IDENTITY.equals(coding))
+                continue;
+            Function<ByteBufferPool, ContentCodec.Decoder> factory = _decoders.get(coding);
+            if (factory == null)
+            {
+                decoders.forEach(ContentCodec.Decoder::destroy);
+                reject(baseRequest, response, coding);
+                return;
+            }
+            decoders.add(factory.apply(byteBufferPool));
+        }
+
+        if (decoders.isEmpty())
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} decompressing {} {}", this, codings, request);
+        _decompressedRequests.increment();
+        baseRequest.getHttpInput().addInterceptor(new DecompressionHttpInputInterceptor(this, decoders, getMaxDecompressedSize()));
+
+        HttpFields.Mutable newFields = HttpFields.build(httpFields.size() + 1);
+        for (HttpField field : httpFields)
+        {
+            if (field.getHeader() == HttpHeader.CONTENT_ENCODING)
+                newFields.add(new HttpField("X-Content-Encoding", field.getValue()));
+            else if (field.getHeader() == HttpHeader.CONTENT_LENGTH)
+                newFields.add(new HttpField("X-Content-Length", field.getValue()));
+            else
+                newFields.add(field);
+        }
+        baseRequest.setHttpFields(newFields);
+
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private void reject(Request baseRequest, HttpServletResponse response, String coding) throws IOException
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} rejecting unsupported content coding {} {}", this, coding, baseRequest);
+        _unsupportedRequests.increment();
+        baseRequest.setHandled(true);
+        response.setHeader(HttpHeader.ACCEPT_ENCODING.asString(), String.join(", ", _decoders.keySet()));
+        response.sendError(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415, "Unsupported content coding " + coding);
+    }
+
+    void onDecompressedSizeExceeded()
+    {
+        _oversizedRequests.increment();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), _decoders.keySet());
+    }
+}
@@ -0,0 +1,183 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.nio.ByteBuffer;
+import java.util.List;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.HttpInput;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.component.Destroyable;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>An {@link HttpInput.Interceptor} that decodes request content
+ * with one or more {@link ContentCodec.Decoder}s.</p>
+ * <p>When the request content has multiple content codings, the decoders are
+ * given in decoding order, that is the reverse of the order in which the
+ * codings were applied, and the output of each decoder is the input of the next.</p>
+ * <p>The request content fails with a {@code 413} status code if the decoded
+ * content exceeds the max decompressed size, and with a {@code 400} status
+ * code if the encoded content is invalid.</p>
+ */
+public class DecompressionHttpInputInterceptor implements HttpInput.Interceptor, Destroyable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(DecompressionHttpInputInterceptor.class);
+
+    private final DecompressionHandler _handler;
+    private final List<ContentCodec.Decoder> _decoders;
+    private final ByteBuffer[] _inputs;
+    private final long _maxDecompressedSize;
+    private long _decompressed;
+    private HttpInput.ErrorContent _failure;
+
+    /**
+     * @param handler the handler that created this interceptor
+     * @param decoders the decoders of the request content, in decoding order
+     * @param maxDecompressedSize the max decompressed size of the request content, or a negative value for no limit
+     */
+    public DecompressionHttpInputInterceptor(DecompressionHandler handler, List<ContentCodec.Decoder> decoders, long maxDecompressedSize)
+    {
+        if (decoders.isEmpty())
+            throw new IllegalArgumentException("No decoders");
+        _handler = handler;
+        _decoders = List.copyOf(decoders);
+        _inputs = new ByteBuffer[_decoders.size()];
+        _maxDecompressedSize = maxDecompressedSize;
+    }
+
+    @Override
+    public HttpInput.Content readFrom(HttpInput.Content content)
+    {
+        if (_failure != null)
+            return _failure;
+
+        ByteBuffer decoded;
+        try
+        {
+            // Special content may be preceded by bytes buffered between decoders.
+            ByteBuffer encoded = content.isSpecial() ? BufferUtil.EMPTY_BUFFER : content.getByteBuffer();
+            decoded = decode(_decoders.size() - 1, encoded);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failing request content, invalid encoding {}", this, x);
+            BadMessageException failure = x instanceof BadMessageException
+                ? (BadMessageException)x
+                : new BadMessageException(HttpStatus.BAD_REQUEST_400, "Invalid content encoding", x);
+            return fail(failure);
+        }
+
+        if (!decoded.hasRemaining())
+        {
+            release(_decoders.size() - 1, decoded);
+            return content.isSpecial() ? content : null;
+        }
+
+        _decompressed += decoded.remaining();
+        if (_maxDecompressedSize >= 0 && _decompressed > _maxDecompressedSize)
+        {
+            release(_decoders.size() - 1, decoded);
+            if (LOG.isDebugEnabled())
+                LOG.debug("Failing request content, max decompressed size exceeded {}", this);
+            _handler.onDecompressedSizeExceeded();
+            return fail(new BadMessageException(HttpStatus.PAYLOAD_TOO_LARGE_413, "Decompressed content too large"));
+        }
+
+        ContentCodec.Decoder decoder = _decoders.get(_decoders.size() - 1);
+        return new HttpInput.Content(decoded)
+        {
+            @Override
+            public void succeeded()
+            {
+                decoder.release(decoded);
+            }
+
+            @Override
+            public void failed(Throwable x)
+            {
+                decoder.release(decoded);
+            }
+        };
+    }
+
+    /**
+     * @param stage the index of the decoder
+     * @param encoded the encoded request content
+     * @return the bytes decoded by the given decoder, empty if more request content is needed
+     */
+    private ByteBuffer decode(int stage, ByteBuffer encoded)
+    {
+        ContentCodec.Decoder decoder = _decoders.get(stage);
+        if (stage == 0)
+            return decoder.decode(encoded);
+
+        while (true)
+        {
+            ByteBuffer input = _inputs[stage];
+            if (input == null || !input.hasRemaining())
+            {
+                if (input != null)
+                    release(stage - 1, input);
+                _inputs[stage] = null;
+                input = decode(stage - 1, encoded);
+                if (!input.hasRemaining())
+                {
+                    release(stage - 1, input);
+                    return BufferUtil.EMPTY_BUFFER;
+                }
+                _inputs[stage] = input;
+            }
+            ByteBuffer decoded = decoder.decode(input);
+            if (decoded.hasRemaining())
+                return decoded;
+            release(stage, decoded);
+        }
+    }
+
+    private void release(int stage, ByteBuffer decoded)
+    {
+        if (decoded != BufferUtil.EMPTY_BUFFER)
+            _decoders.get(stage).release(decoded);
+    }
+
+    private HttpInput.Content fail(BadMessageException failure)
+    {
+        _failure = new HttpInput.ErrorContent(failure);
+        return _failure;
+    }
+
+    @Override
+    public void destroy()
+    {
+        for (int i = 0; i < _decoders.size(); ++i)
+        {
+            if (i > 0 && _inputs[i] != null)
+                release(i - 1, _inputs[i]);
+            _inputs[i] = null;
+            _decoders.get(i).destroy();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[decompressed=%d/%d]", getClass().getSimpleName(), hashCode(), _decompressed, _maxDecompressedSize);
+    }
+}
@@ -0,0 +1,94 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.nio.ByteBuffer;
+import java.util.zip.DataFormatException;
+import java.util.zip.Inflater;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.util.BufferUtil;
+
+/**
+ * <p>A decoder of the {@code deflate} content coding, that is the zlib format.</p>
+ * <p>Raw deflate content without the zlib header, sent by some non-compliant
+ * clients, is detected and decoded as well.</p>
+ */
+class DeflateContentDecoder implements ContentCodec.Decoder
+{
+    private static final int BUFFER_SIZE = 8192;
+
+    private Inflater _inflater;
+
+    @Override
+    public ByteBuffer decode(ByteBuffer buffer)
+    {
+        if (_inflater == null)
+        {
+            if (!buffer.hasRemaining())
+                return BufferUtil.EMPTY_BUFFER;
+            _inflater = new Inflater(!isZlibHeader(buffer));
+        }
+
+        ByteBuffer decoded = ByteBuffer.allocate(BUFFER_SIZE);
+        try
+        {
+            while (decoded.hasRemaining() && !_inflater.finished())
+            {
+                if (_inflater.needsInput())
+                {
+                    if (!buffer.hasRemaining())
+                        break;
+                    _inflater.setInput(buffer);
+                }
+                if (_inflater.inflate(decoded) == 0)
+                {
+                    if (_inflater.needsDictionary())
+                        throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Unsupported deflate dictionary");
+                    if (!_inflater.needsInput() && !_inflater.finished())
+                        break;
+                }
+            }
+        }
+        catch (DataFormatException x)
+        {
+            throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Invalid deflate content", x);
+        }
+
+        // Bytes after the end of the deflate stream are ignored.
+        if (_inflater.finished())
+            buffer.position(buffer.limit());
+
+        decoded.flip();
+        return decoded;
+    }
+
+    private static boolean isZlibHeader(ByteBuffer buffer)
+    {
+        if (buffer.remaining() < 2)
+            return true;
+        int cmf = buffer.get(buffer.position()) & 0xFF;
+        int flg = buffer.get(buffer.position() + 1) & 0xFF;
+        return (cmf & 0x0F) == 8 && ((cmf << 8) | flg) % 31 == 0;
+    }
+
+    @Override
+    public void destroy()
+    {
+        if (_inflater != null)
+            _inflater.end();
+    }
+}
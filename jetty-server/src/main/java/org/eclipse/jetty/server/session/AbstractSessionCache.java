@@ -661,6 +661,105 @@ public abstract class AbstractSessionCache extends ContainerLifeCycle implements
         return doDelete(id);
     }
 
+    /**
+     * Remove a session object from this cache only, leaving the backing
+     * store untouched, so that the next request loads it afresh.
+     * Sessions that are in use by a request are not evicted.
+     *
+     * @param id the session id
+     * @return true if the session was evicted
+     */
+    public boolean evict(String id)
+    {
+        Session session = doGet(id);
+        if (session == null)
+            return false;
+
+        try (AutoLock lock = session.lock())
+        {
+            if (session.getRequests() > 0)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Not evicting session id={} in use", id);
+                return false;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Evicting session id={}", id);
+            session.setResident(false);
+            doDelete(id);
+            return true;
+        }
+    }
+
+    /**
+     * Get the ids of the sessions currently held by this cache.
+     * Caches that do not hold sessions return an empty set.
+     *
+     * @return a snapshot of the ids of the cached sessions
+     */
+    protected Set<String> getCachedSessionIds()
+    {
+        return Collections.emptySet();
+    }
+
+    /**
+     * Passivate and store the cached sessions that are not in use by a request,
+     * and remove them from this cache, so that another node can load them
+     * promptly. Sessions that are in use by a request are left in the cache.
+     *
+     * @return the ids of the drained sessions
+     */
+    public Set<String> drain()
+    {
+        Set<String> drained = new HashSet<>();
+        for (String id : getCachedSessionIds())
+        {
+            Session session = doGet(id);
+            if (session != null && drain(session))
+                drained.add(id);
+        }
+        return drained;
+    }
+
+    private boolean drain(Session session)
+    {
+        try (AutoLock lock = session.lock())
+        {
+            if (session.getRequests() > 0 || !session.isValid() || !session.isResident())
+                return false;
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Draining session {}", session.getId());
+
+            if (_sessionDataStore.isPassivating())
+                session.willPassivate();
+            //Fake being dirty to force the write
+            session.getSessionData().setDirty(true);
+            _sessionDataStore.store(session.getId(), session.getSessionData());
+            doDelete(session.getId());
+            session.setResident(false);
+            return true;
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Unable to drain session {}", session.getId(), e);
+            return false;
+        }
+    }
+
+    /**
+     * Load a session into this cache, if it is not already cached,
+     * without the session being entered by a request.
+     *
+     * @param id the session id
+     * @return true if the session is in the cache
+     * @throws Exception if the session cannot be loaded
+     */
+    public boolean prewarm(String id) throws Exception
+    {
+        return getAndEnter(id, false) != null;
+    }
+
     @Override
     public Set<String> checkExpiration(Set<String> candidates)
     {
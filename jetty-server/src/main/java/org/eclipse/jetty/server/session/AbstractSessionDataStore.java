@@ -41,6 +41,12 @@ public abstract class AbstractSessionDataStore extends ContainerLifeCycle implem
     protected long _lastExpiryCheckTime = 0; //last time in ms that getExpired was called
     protected long _lastOrphanSweepTime = 0; //last time in ms that we deleted orphaned sessions
     protected int _savePeriodSec = DEFAULT_SAVE_PERIOD_SEC; //time in sec between saves
+    private SessionDataSerialization _serialization = new SessionDataSerialization();
+
+    public AbstractSessionDataStore()
+    {
+        addBean(_serialization);
+    }
     
     /**
      * Check if a session for the given id exists.
@@ -367,6 +373,24 @@ public abstract class AbstractSessionDataStore extends ContainerLifeCycle implem
         _savePeriodSec = savePeriodSec;
     }
 
+    /**
+     * @return the serialization of the session attributes, for stores that serialize them
+     */
+    public SessionDataSerialization getSessionDataSerialization()
+    {
+        return _serialization;
+    }
+
+    /**
+     * @param serialization the serialization of the session attributes, for stores that serialize them
+     */
+    public void setSessionDataSerialization(SessionDataSerialization serialization)
+    {
+        checkStarted();
+        updateBean(_serialization, serialization);
+        _serialization = serialization;
+    }
+
     @Override
     public String toString()
     {
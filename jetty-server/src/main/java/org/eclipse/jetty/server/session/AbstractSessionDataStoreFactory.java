@@ -13,14 +13,21 @@
 
 package org.eclipse.jetty.server.session;
 
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
+
 /**
  * AbstractSessionDataStoreFactory
  */
-public abstract class AbstractSessionDataStoreFactory implements SessionDataStoreFactory
+public abstract class AbstractSessionDataStoreFactory implements SessionDataStoreFactory, Validatable
 {
 
     int _gracePeriodSec = AbstractSessionDataStore.DEFAULT_GRACE_PERIOD_SEC;
     int _savePeriodSec = AbstractSessionDataStore.DEFAULT_SAVE_PERIOD_SEC;
+    String _serializationCodec;
+    String _serializationCompression;
+    int _serializationCompressionThreshold = SessionDataSerialization.DEFAULT_COMPRESSION_THRESHOLD;
+    int _serializationWarnSize = -1;
 
     /**
      * @return the gracePeriodSec
@@ -53,4 +60,99 @@ public abstract class AbstractSessionDataStoreFactory implements SessionDataStor
     {
         _savePeriodSec = savePeriodSec;
     }
+
+    /**
+     * @return the name of the {@link SessionDataCodec} of the stores, or null for {@code java}
+     */
+    public String getSerializationCodec()
+    {
+        return _serializationCodec;
+    }
+
+    /**
+     * @param codec the name of the {@link SessionDataCodec} of the stores, or null for {@code java}
+     */
+    public void setSerializationCodec(String codec)
+    {
+        _serializationCodec = codec;
+    }
+
+    /**
+     * @return the content coding used by the stores to compress sessions, or null for no compression
+     */
+    public String getSerializationCompression()
+    {
+        return _serializationCompression;
+    }
+
+    /**
+     * @param compression the content coding used by the stores to compress sessions, or null for no compression
+     */
+    public void setSerializationCompression(String compression)
+    {
+        _serializationCompression = compression;
+    }
+
+    /**
+     * @return the min serialized size in bytes of the sessions to compress
+     */
+    public int getSerializationCompressionThreshold()
+    {
+        return _serializationCompressionThreshold;
+    }
+
+    /**
+     * @param compressionThreshold the min serialized size in bytes of the sessions to compress
+     */
+    public void setSerializationCompressionThreshold(int compressionThreshold)
+    {
+        _serializationCompressionThreshold = compressionThreshold;
+    }
+
+    /**
+     * @return the serialized size in bytes above which a warning is logged, or -1 to never warn
+     */
+    public int getSerializationWarnSize()
+    {
+        return _serializationWarnSize;
+    }
+
+    /**
+     * @param warnSize the serialized size in bytes above which a warning is logged, or -1 to never warn
+     */
+    public void setSerializationWarnSize(int warnSize)
+    {
+        _serializationWarnSize = warnSize;
+    }
+
+    /**
+     * Check that the configured serialization codec and compression are available.
+     *
+     * @param report the report to add the problems to
+     */
+    @Override
+    public void validate(ValidationReport report)
+    {
+        try
+        {
+            configure(new SessionDataSerialization());
+        }
+        catch (IllegalArgumentException x)
+        {
+            report.addProblem(this, x.getMessage());
+        }
+    }
+
+    /**
+     * Configure the serialization of a store created by this factory.
+     *
+     * @param serialization the serialization to configure
+     */
+    protected void configure(SessionDataSerialization serialization)
+    {
+        serialization.setCodecName(getSerializationCodec());
+        serialization.setCompression(getSerializationCompression());
+        serialization.setCompressionThreshold(getSerializationCompressionThreshold());
+        serialization.setWarnSize(getSerializationWarnSize());
+    }
 }
@@ -0,0 +1,214 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Set;
+import java.util.UUID;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * AbstractSessionEventBroadcaster
+ *
+ * Base class for {@link SessionEventBroadcaster}s, that encodes events to
+ * bytes, so that implementations only have to {@link #send(byte[]) send}
+ * and {@link #receive(byte[]) receive} bytes.
+ * Every broadcaster has a unique origin that is sent along with each event,
+ * so that events published by this broadcaster and received back, as it is
+ * normal with multicast or pub/sub transports, are discarded.
+ */
+@ManagedObject
+public abstract class AbstractSessionEventBroadcaster extends AbstractLifeCycle implements SessionEventBroadcaster
+{
+    private static final Logger LOG = LoggerFactory.getLogger(AbstractSessionEventBroadcaster.class);
+    private static final int VERSION = 1;
+
+    private final List<Listener> _listeners = new CopyOnWriteArrayList<>();
+    private final String _origin = UUID.randomUUID().toString();
+    private final LongAdder _published = new LongAdder();
+    private final LongAdder _received = new LongAdder();
+
+    @Override
+    public void addListener(Listener listener)
+    {
+        _listeners.add(listener);
+    }
+
+    @Override
+    public void removeListener(Listener listener)
+    {
+        _listeners.remove(listener);
+    }
+
+    /**
+     * @return the unique origin of the events published by this broadcaster
+     */
+    public String getOrigin()
+    {
+        return _origin;
+    }
+
+    @ManagedAttribute(value = "The number of session events published", readonly = true)
+    public long getEventsPublished()
+    {
+        return _published.sum();
+    }
+
+    @ManagedAttribute(value = "The number of session events received from other nodes", readonly = true)
+    public long getEventsReceived()
+    {
+        return _received.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _published.reset();
+        _received.reset();
+    }
+
+    @Override
+    public void publish(SessionEvent event)
+    {
+        if (!isRunning())
+            return;
+        try
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Publishing {}", event);
+            send(encode(event));
+            _published.increment();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to publish {}", event, x);
+        }
+    }
+
+    /**
+     * Sends the given bytes to the other nodes.
+     *
+     * @param bytes the encoded event
+     * @throws Exception if the bytes cannot be sent
+     */
+    protected abstract void send(byte[] bytes) throws Exception;
+
+    /**
+     * Called by implementations when bytes have been received from the transport.
+     *
+     * @param bytes the encoded event
+     */
+    protected void receive(byte[] bytes)
+    {
+        SessionEvent event;
+        try
+        {
+            event = decode(bytes);
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to decode session event", x);
+            return;
+        }
+
+        // Our own event.
+        if (event == null)
+            return;
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Received {}", event);
+        _received.increment();
+        for (Listener listener : _listeners)
+        {
+            try
+            {
+                listener.onSessionEvent(event);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    protected byte[] encode(SessionEvent event) throws IOException
+    {
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+        try (DataOutputStream output = new DataOutputStream(bytes))
+        {
+            output.writeByte(VERSION);
+            output.writeUTF(_origin);
+            output.writeByte(event.getType().ordinal());
+            output.writeUTF(event.getWorkerName() == null ? "" : event.getWorkerName());
+            output.writeUTF(event.getVhost());
+            output.writeUTF(event.getCanonicalContextPath());
+            output.writeUTF(event.getId());
+            output.writeInt(event.getAttributeNames().size());
+            for (String name : event.getAttributeNames())
+            {
+                output.writeUTF(name);
+            }
+        }
+        return bytes.toByteArray();
+    }
+
+    /**
+     * @param bytes the encoded event
+     * @return the decoded event, or null if the event was published by this broadcaster
+     * @throws IOException if the bytes cannot be decoded
+     */
+    protected SessionEvent decode(byte[] bytes) throws IOException
+    {
+        try (DataInputStream input = new DataInputStream(new ByteArrayInputStream(bytes)))
+        {
+            int version = input.readUnsignedByte();
+            if (version != VERSION)
+                throw new IOException("Unsupported session event version " + version);
+            if (_origin.equals(input.readUTF()))
+                return null;
+            SessionEvent.Type type = SessionEvent.Type.values()[input.readUnsignedByte()];
+            String workerName = input.readUTF();
+            String vhost = input.readUTF();
+            String contextPath = input.readUTF();
+            String id = input.readUTF();
+            int count = input.readInt();
+            Set<String> names = new HashSet<>();
+            for (int i = 0; i < count; ++i)
+            {
+                names.add(input.readUTF());
+            }
+            return new SessionEvent(type, workerName.isEmpty() ? null : workerName, vhost, contextPath, id, names);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[origin=%s]", getClass().getSimpleName(), hashCode(), _origin);
+    }
+}
@@ -13,7 +13,9 @@
 
 package org.eclipse.jetty.server.session;
 
+import java.util.HashSet;
 import java.util.Objects;
+import java.util.Set;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
 import java.util.function.Function;
@@ -102,6 +104,12 @@ public class DefaultSessionCache extends AbstractSessionCache
         return _sessions.get(id);
     }
 
+    @Override
+    protected Set<String> getCachedSessionIds()
+    {
+        return new HashSet<>(_sessions.keySet());
+    }
+
     @Override
     public Session doPutIfAbsent(String id, Session session)
     {
@@ -20,7 +20,6 @@ import java.io.FileInputStream;
 import java.io.FileOutputStream;
 import java.io.IOException;
 import java.io.InputStream;
-import java.io.ObjectOutputStream;
 import java.io.OutputStream;
 import java.nio.file.FileVisitOption;
 import java.nio.file.Files;
@@ -32,7 +31,6 @@ import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.TimeUnit;
 import java.util.stream.Stream;
 
-import org.eclipse.jetty.util.ClassLoadingObjectInputStream;
 import org.eclipse.jetty.util.MultiException;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
@@ -477,8 +475,7 @@ public class FileSessionDataStore extends AbstractSessionDataStore
         out.writeLong(data.getExpiry());
         out.writeLong(data.getMaxInactiveMs());
 
-        ObjectOutputStream oos = new ObjectOutputStream(out);
-        SessionData.serializeAttributes(data, oos);
+        getSessionDataSerialization().serializeAttributes(data, out);
     }
 
     /**
@@ -622,8 +619,7 @@ public class FileSessionDataStore extends AbstractSessionDataStore
             data.setMaxInactiveMs(maxIdle);
 
             // Attributes
-            ClassLoadingObjectInputStream ois = new ClassLoadingObjectInputStream(is);
-            SessionData.deserializeAttributes(data, ois);
+            getSessionDataSerialization().deserializeAttributes(data, is);
             return data;
         }
         catch (Exception e)
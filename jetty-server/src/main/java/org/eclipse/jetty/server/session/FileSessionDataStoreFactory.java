@@ -63,6 +63,7 @@ public class FileSessionDataStoreFactory extends AbstractSessionDataStoreFactory
         fsds.setStoreDir(getStoreDir());
         fsds.setGracePeriodSec(getGracePeriodSec());
         fsds.setSavePeriodSec(getSavePeriodSec());
+        configure(fsds.getSessionDataSerialization());
         return fsds;
     }
 }
@@ -14,22 +14,28 @@
 package org.eclipse.jetty.server.session;
 
 import java.io.ByteArrayInputStream;
-import java.io.ByteArrayOutputStream;
 import java.io.InputStream;
-import java.io.ObjectOutputStream;
 import java.sql.Connection;
 import java.sql.DatabaseMetaData;
 import java.sql.PreparedStatement;
 import java.sql.ResultSet;
 import java.sql.SQLException;
 import java.sql.Statement;
+import java.util.ArrayList;
 import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
 import java.util.Set;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
 
-import org.eclipse.jetty.util.ClassLoadingObjectInputStream;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.thread.AutoLock;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -52,6 +58,15 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     protected DatabaseAdaptor _dbAdaptor;
     protected SessionTableSchema _sessionTableSchema;
     protected boolean _schemaProvided;
+    protected int _scavengeBatchSize = 0;
+    protected int _writeBatchSize = 0;
+    protected int _transactionIsolation = -1;
+
+    private final AutoLock _lock = new AutoLock();
+    private final Map<String, PendingUpdate> _pendingUpdates = new LinkedHashMap<>();
+    private final LongAdder _statements = new LongAdder();
+    private final LongAdder _statementNanos = new LongAdder();
+    private final LongAdder _batches = new LongAdder();
 
     private static final ByteArrayInputStream EMPTY = new ByteArrayInputStream(new byte[0]);
 
@@ -480,6 +495,37 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
 
         }
 
+        public PreparedStatement getOrphanedSessionsStatement(Connection connection, long timeLimit)
+            throws Exception
+        {
+            if (_dbAdaptor == null)
+                throw new IllegalStateException("No DB adaptor");
+
+            PreparedStatement statement = connection.prepareStatement("select " + getIdColumn() + ", " +
+                getContextPathColumn() + ", " + getVirtualHostColumn() +
+                " from " + getSchemaTableName() + " where " +
+                getExpiryTimeColumn() + " > 0 and " + getExpiryTimeColumn() + " <= ?");
+            statement.setLong(1, timeLimit);
+            return statement;
+        }
+
+        /**
+         * @param connection the connection to prepare the statement on
+         * @return a statement to delete a session, whose id, context path and
+         * virtual host parameters are to be set for each session to delete
+         * @throws Exception if the statement cannot be prepared
+         */
+        public PreparedStatement getDeleteSessionsStatement(Connection connection)
+            throws Exception
+        {
+            if (_dbAdaptor == null)
+                throw new IllegalStateException("No DB adaptor");
+
+            return connection.prepareStatement("delete from " + getSchemaTableName() +
+                " where " + getIdColumn() + " = ? and " + getContextPathColumn() +
+                " = ? and " + getVirtualHostColumn() + " = ?");
+        }
+
         /**
          * Set up the tables in the database
          *
@@ -615,6 +661,14 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     @Override
     protected void doStop() throws Exception
     {
+        try
+        {
+            flush();
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Unable to write batched session updates", e);
+        }
         super.doStop();
         _initialized = false;
         if (!_schemaProvided)
@@ -643,9 +697,11 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     @Override
     public SessionData doLoad(String id) throws Exception
     {
+        flushIfPending(id);
+
         try (Connection connection = _dbAdaptor.getConnection();
              PreparedStatement statement = _sessionTableSchema.getLoadStatement(connection, id, _context);
-             ResultSet result = statement.executeQuery())
+             ResultSet result = executeQuery(statement))
         {
             SessionData data = null;
             if (result.next())
@@ -662,10 +718,9 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
                 data.setContextPath(_context.getCanonicalContextPath());
                 data.setVhost(_context.getVhost());
 
-                try (InputStream is = _dbAdaptor.getBlobInputStream(result, _sessionTableSchema.getMapColumn());
-                     ClassLoadingObjectInputStream ois = new ClassLoadingObjectInputStream(is))
+                try (InputStream is = _dbAdaptor.getBlobInputStream(result, _sessionTableSchema.getMapColumn()))
                 {
-                    SessionData.deserializeAttributes(data, ois);
+                    getSessionDataSerialization().deserializeAttributes(data, is);
                 }
                 catch (Exception e)
                 {
@@ -685,11 +740,16 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     @Override
     public boolean delete(String id) throws Exception
     {
+        try (AutoLock l = _lock.lock())
+        {
+            _pendingUpdates.remove(id);
+        }
+
         try (Connection connection = _dbAdaptor.getConnection();
              PreparedStatement statement = _sessionTableSchema.getDeleteStatement(connection, id, _context))
         {
             connection.setAutoCommit(true);
-            int rows = statement.executeUpdate();
+            int rows = executeUpdate(statement);
             if (LOG.isDebugEnabled())
                 LOG.debug("Deleted Session {}:{}", id, (rows > 0));
 
@@ -707,6 +767,10 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
         {
             doInsert(id, data);
         }
+        else if (_writeBatchSize > 0)
+        {
+            doBatchUpdate(id, data);
+        }
         else
         {
             doUpdate(id, data);
@@ -741,16 +805,11 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
                 statement.setLong(10, data.getExpiry());
                 statement.setLong(11, data.getMaxInactiveMs());
 
-                try (ByteArrayOutputStream baos = new ByteArrayOutputStream();
-                     ObjectOutputStream oos = new ObjectOutputStream(baos))
-                {
-                    SessionData.serializeAttributes(data, oos);
-                    byte[] bytes = baos.toByteArray();
-                    ByteArrayInputStream bais = new ByteArrayInputStream(bytes);
-                    statement.setBinaryStream(12, bais, bytes.length); //attribute map as blob
-                }
+                byte[] bytes = getSessionDataSerialization().serializeAttributes(data);
+                ByteArrayInputStream bais = new ByteArrayInputStream(bytes);
+                statement.setBinaryStream(12, bais, bytes.length); //attribute map as blob
 
-                statement.executeUpdate();
+                executeUpdate(statement);
                 if (LOG.isDebugEnabled())
                     LOG.debug("Inserted session {}", data);
             }
@@ -772,18 +831,13 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
                 statement.setLong(5, data.getExpiry());
                 statement.setLong(6, data.getMaxInactiveMs());
 
-                try (ByteArrayOutputStream baos = new ByteArrayOutputStream();
-                     ObjectOutputStream oos = new ObjectOutputStream(baos))
+                byte[] bytes = getSessionDataSerialization().serializeAttributes(data);
+                try (ByteArrayInputStream bais = new ByteArrayInputStream(bytes))
                 {
-                    SessionData.serializeAttributes(data, oos);
-                    byte[] bytes = baos.toByteArray();
-                    try (ByteArrayInputStream bais = new ByteArrayInputStream(bytes))
-                    {
-                        statement.setBinaryStream(7, bais, bytes.length); //attribute map as blob
-                    }
+                    statement.setBinaryStream(7, bais, bytes.length); //attribute map as blob
                 }
 
-                statement.executeUpdate();
+                executeUpdate(statement);
 
                 if (LOG.isDebugEnabled())
                     LOG.debug("Updated session {}", data);
@@ -791,12 +845,139 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
         }
     }
 
+    /**
+     * <p>Queues the update of a session, to be written in a batch with the
+     * updates of other sessions when {@link #getWriteBatchSize()} updates
+     * are pending, when the session is loaded, when expired sessions are
+     * checked, or when this store is stopped.</p>
+     *
+     * @param id the session id
+     * @param data the session data
+     * @throws Exception if the batch could not be written
+     */
+    protected void doBatchUpdate(String id, SessionData data)
+        throws Exception
+    {
+        PendingUpdate update = new PendingUpdate(id, data, getSessionDataSerialization().serializeAttributes(data));
+
+        List<PendingUpdate> batch = null;
+        try (AutoLock l = _lock.lock())
+        {
+            // Only the most recent update of a session needs to be written.
+            _pendingUpdates.remove(id);
+            _pendingUpdates.put(id, update);
+            if (_pendingUpdates.size() >= _writeBatchSize)
+                batch = drainPendingUpdates();
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Queued update of session {}", data);
+
+        if (batch != null)
+            writeBatch(batch);
+    }
+
+    /**
+     * <p>Writes the pending batched session updates.</p>
+     *
+     * @throws Exception if the batch could not be written
+     */
+    @ManagedOperation(value = "writes the pending batched session updates", impact = "ACTION")
+    public void flush() throws Exception
+    {
+        List<PendingUpdate> batch;
+        try (AutoLock l = _lock.lock())
+        {
+            batch = drainPendingUpdates();
+        }
+        if (!batch.isEmpty())
+            writeBatch(batch);
+    }
+
+    private void flushIfPending(String id) throws Exception
+    {
+        boolean pending;
+        try (AutoLock l = _lock.lock())
+        {
+            pending = _pendingUpdates.containsKey(id);
+        }
+        if (pending)
+            flush();
+    }
+
+    private List<PendingUpdate> drainPendingUpdates()
+    {
+        assert _lock.isHeldByCurrentThread();
+        List<PendingUpdate> batch = new ArrayList<>(_pendingUpdates.values());
+        _pendingUpdates.clear();
+        return batch;
+    }
+
+    private void writeBatch(List<PendingUpdate> batch) throws Exception
+    {
+        try (Connection connection = _dbAdaptor.getConnection())
+        {
+            int isolation = beginTransaction(connection);
+            try (PreparedStatement statement = _sessionTableSchema.getUpdateSessionStatement(connection, batch.get(0)._id, _context))
+            {
+                for (PendingUpdate update : batch)
+                {
+                    statement.setString(1, update._lastNode);
+                    statement.setLong(2, update._accessed);
+                    statement.setLong(3, update._lastAccessed);
+                    statement.setLong(4, update._lastSaved);
+                    statement.setLong(5, update._expiry);
+                    statement.setLong(6, update._maxInactiveMs);
+                    statement.setBinaryStream(7, new ByteArrayInputStream(update._attributes), update._attributes.length);
+                    statement.setString(8, update._id);
+                    statement.addBatch();
+                }
+                executeBatch(statement);
+                connection.commit();
+            }
+            catch (Exception e)
+            {
+                connection.rollback();
+                throw e;
+            }
+            finally
+            {
+                endTransaction(connection, isolation);
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Updated {} sessions in batch", batch.size());
+        }
+        catch (Exception e)
+        {
+            // Requeue the updates that have not been superseded by more recent ones.
+            try (AutoLock l = _lock.lock())
+            {
+                for (PendingUpdate update : batch)
+                {
+                    _pendingUpdates.putIfAbsent(update._id, update);
+                }
+            }
+            throw e;
+        }
+    }
+
     @Override
     public Set<String> doCheckExpired(Set<String> candidates, long time)
     {
         if (LOG.isDebugEnabled())
             LOG.debug("Getting expired sessions at time {}", time);
         
+        try
+        {
+            //make sure the expiry times in the db are up to date
+            flush();
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Unable to write batched session updates", e);
+        }
+
         Set<String> expiredSessionKeys = new HashSet<>();
         try (Connection connection = _dbAdaptor.getConnection())
         {
@@ -810,7 +991,7 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
 
             try (PreparedStatement statement = _sessionTableSchema.getMyExpiredSessionsStatement(connection, _context, upperBound))
             {
-                try (ResultSet result = statement.executeQuery())
+                try (ResultSet result = executeQuery(statement))
                 {
                     while (result.next())
                     {
@@ -844,7 +1025,7 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
                     for (String k : notExpiredInDB)
                     {
                         checkSessionExists.setString(1, k);
-                        try (ResultSet result = checkSessionExists.executeQuery())
+                        try (ResultSet result = executeQuery(checkSessionExists))
                         {
                             if (!result.next())
                             {
@@ -889,7 +1070,7 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
                 if (LOG.isDebugEnabled()) 
                     LOG.debug("{}- Searching for sessions for context {} expired before {}", _context.getWorkerName(), _context.getCanonicalContextPath(), timeLimit);
 
-                try (ResultSet result = selectExpiredSessions.executeQuery())
+                try (ResultSet result = executeQuery(selectExpiredSessions))
                 {
                     while (result.next())
                     {
@@ -914,12 +1095,18 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     @Override
     public void doCleanOrphans(long time)
     {
+        if (_scavengeBatchSize > 0)
+        {
+            doCleanOrphansInBatches(time);
+            return;
+        }
+
         //Harshly delete sessions for any node and context that expired at or before the timeLimit
         try (Connection connection = _dbAdaptor.getConnection();
              PreparedStatement statement = _sessionTableSchema.getCleanOrphansStatement(connection, time))
         {
             connection.setAutoCommit(true);
-            int rows = statement.executeUpdate();
+            int rows = executeUpdate(statement);
             if (LOG.isDebugEnabled())
                 LOG.debug("Deleted {} orphaned sessions", rows);
         }
@@ -929,6 +1116,142 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
         } 
     }
 
+    /**
+     * <p>Deletes the sessions for any node and context that expired at or before
+     * the given time, at most {@link #getScavengeBatchSize()} sessions per transaction,
+     * so that the rows of the session table are not locked for long periods.</p>
+     *
+     * @param time the upper limit of the expiry time to check in msec
+     */
+    protected void doCleanOrphansInBatches(long time)
+    {
+        try (Connection connection = _dbAdaptor.getConnection())
+        {
+            int total = 0;
+            while (true)
+            {
+                int rows = deleteOrphans(connection, time);
+                total += rows;
+                // Stop when there are no more orphans, or they could not be deleted.
+                if (rows < _scavengeBatchSize)
+                    break;
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Deleted {} orphaned sessions in batches of {}", total, _scavengeBatchSize);
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Error cleaning orphan sessions", e);
+        }
+    }
+
+    private int deleteOrphans(Connection connection, long time) throws Exception
+    {
+        int isolation = beginTransaction(connection);
+        try (PreparedStatement select = _sessionTableSchema.getOrphanedSessionsStatement(connection, time);
+             PreparedStatement delete = _sessionTableSchema.getDeleteSessionsStatement(connection))
+        {
+            select.setMaxRows(_scavengeBatchSize);
+            int selected = 0;
+            try (ResultSet result = executeQuery(select))
+            {
+                while (result.next())
+                {
+                    delete.setString(1, result.getString(_sessionTableSchema.getIdColumn()));
+                    delete.setString(2, result.getString(_sessionTableSchema.getContextPathColumn()));
+                    delete.setString(3, result.getString(_sessionTableSchema.getVirtualHostColumn()));
+                    delete.addBatch();
+                    ++selected;
+                }
+            }
+
+            int deleted = 0;
+            if (selected > 0)
+            {
+                for (int rows : executeBatch(delete))
+                {
+                    if (rows > 0)
+                        deleted += rows;
+                    else if (rows == Statement.SUCCESS_NO_INFO)
+                        ++deleted;
+                }
+            }
+            connection.commit();
+            return deleted;
+        }
+        catch (Exception e)
+        {
+            connection.rollback();
+            throw e;
+        }
+        finally
+        {
+            endTransaction(connection, isolation);
+        }
+    }
+
+    private int beginTransaction(Connection connection) throws SQLException
+    {
+        int isolation = connection.getTransactionIsolation();
+        if (_transactionIsolation >= 0)
+            connection.setTransactionIsolation(_transactionIsolation);
+        connection.setAutoCommit(false);
+        return isolation;
+    }
+
+    private void endTransaction(Connection connection, int isolation) throws SQLException
+    {
+        connection.setAutoCommit(true);
+        if (_transactionIsolation >= 0)
+            connection.setTransactionIsolation(isolation);
+    }
+
+    private ResultSet executeQuery(PreparedStatement statement) throws SQLException
+    {
+        long begin = NanoTime.now();
+        try
+        {
+            return statement.executeQuery();
+        }
+        finally
+        {
+            onStatementExecuted(begin);
+        }
+    }
+
+    private int executeUpdate(PreparedStatement statement) throws SQLException
+    {
+        long begin = NanoTime.now();
+        try
+        {
+            return statement.executeUpdate();
+        }
+        finally
+        {
+            onStatementExecuted(begin);
+        }
+    }
+
+    private int[] executeBatch(PreparedStatement statement) throws SQLException
+    {
+        long begin = NanoTime.now();
+        try
+        {
+            return statement.executeBatch();
+        }
+        finally
+        {
+            _batches.increment();
+            onStatementExecuted(begin);
+        }
+    }
+
+    private void onStatementExecuted(long begin)
+    {
+        _statements.increment();
+        _statementNanos.add(NanoTime.since(begin));
+    }
+
     public void setDatabaseAdaptor(DatabaseAdaptor dbAdaptor)
     {
         checkStarted();
@@ -944,6 +1267,106 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
         _schemaProvided = true;
     }
 
+    @ManagedAttribute(value = "max number of orphaned sessions deleted per transaction", readonly = true)
+    public int getScavengeBatchSize()
+    {
+        return _scavengeBatchSize;
+    }
+
+    /**
+     * <p>Sets the max number of orphaned sessions deleted per transaction.</p>
+     * <p>By default the value is 0, which means that all the orphaned sessions
+     * are deleted by a single statement, potentially locking many rows of the
+     * session table for a long time on large clusters.</p>
+     *
+     * @param scavengeBatchSize the max number of orphaned sessions deleted per transaction
+     */
+    public void setScavengeBatchSize(int scavengeBatchSize)
+    {
+        _scavengeBatchSize = scavengeBatchSize;
+    }
+
+    @ManagedAttribute(value = "number of session updates written in a batch", readonly = true)
+    public int getWriteBatchSize()
+    {
+        return _writeBatchSize;
+    }
+
+    /**
+     * <p>Sets the number of session updates written in a single batch.</p>
+     * <p>By default the value is 0, which means that sessions are written
+     * when the last request exits them. A positive value means that the
+     * updates of existing sessions are queued and written in a single
+     * transaction, trading the freshness of the sessions in the database for
+     * a lower database load: this is only suitable when requests for a session
+     * are always routed to the same node. New sessions are always written
+     * immediately.</p>
+     *
+     * @param writeBatchSize the number of session updates written in a batch
+     */
+    public void setWriteBatchSize(int writeBatchSize)
+    {
+        checkStarted();
+        _writeBatchSize = writeBatchSize;
+    }
+
+    @ManagedAttribute(value = "isolation level of the batch transactions", readonly = true)
+    public int getTransactionIsolation()
+    {
+        return _transactionIsolation;
+    }
+
+    /**
+     * @param transactionIsolation the {@link Connection} isolation level of the
+     * transactions used for batches, or -1 to use the connection default
+     */
+    public void setTransactionIsolation(int transactionIsolation)
+    {
+        _transactionIsolation = transactionIsolation;
+    }
+
+    @ManagedAttribute("number of pending batched session updates")
+    public int getPendingUpdateCount()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _pendingUpdates.size();
+        }
+    }
+
+    @ManagedAttribute("number of statements executed")
+    public long getStatementCount()
+    {
+        return _statements.longValue();
+    }
+
+    @ManagedAttribute("number of statement batches executed")
+    public long getStatementBatchCount()
+    {
+        return _batches.longValue();
+    }
+
+    @ManagedAttribute("total time in ms spent executing statements")
+    public long getStatementTimeTotal()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(_statementNanos.longValue());
+    }
+
+    @ManagedAttribute("mean time in ms spent executing a statement")
+    public double getStatementTimeMean()
+    {
+        long count = _statements.longValue();
+        return count == 0 ? 0.0D : _statementNanos.longValue() / 1_000_000.0D / count;
+    }
+
+    @ManagedOperation(value = "reset statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _statements.reset();
+        _statementNanos.reset();
+        _batches.reset();
+    }
+
     @Override
     @ManagedAttribute(value = "does this store serialize sessions", readonly = true)
     public boolean isPassivating()
@@ -955,6 +1378,8 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
     public boolean doExists(String id)
         throws Exception
     {
+        flushIfPending(id);
+
         try (Connection connection = _dbAdaptor.getConnection())
         {
             connection.setAutoCommit(true);
@@ -963,7 +1388,7 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
             try (PreparedStatement checkSessionExists = _sessionTableSchema.getCheckSessionExistsStatement(connection, _context))
             {
                 checkSessionExists.setString(1, id);
-                try (ResultSet result = checkSessionExists.executeQuery())
+                try (ResultSet result = executeQuery(checkSessionExists))
                 {
                     if (!result.next())
                     {
@@ -981,4 +1406,31 @@ public class JDBCSessionDataStore extends AbstractSessionDataStore
             }
         }
     }
+
+    /**
+     * A snapshot of a session update waiting to be written in a batch.
+     */
+    private static class PendingUpdate
+    {
+        private final String _id;
+        private final String _lastNode;
+        private final long _accessed;
+        private final long _lastAccessed;
+        private final long _lastSaved;
+        private final long _expiry;
+        private final long _maxInactiveMs;
+        private final byte[] _attributes;
+
+        private PendingUpdate(String id, SessionData data, byte[] attributes)
+        {
+            _id = id;
+            _lastNode = data.getLastNode();
+            _accessed = data.getAccessed();
+            _lastAccessed = data.getLastAccessed();
+            _lastSaved = data.getLastSaved();
+            _expiry = data.getExpiry();
+            _maxInactiveMs = data.getMaxInactiveMs();
+            _attributes = attributes;
+        }
+    }
 }
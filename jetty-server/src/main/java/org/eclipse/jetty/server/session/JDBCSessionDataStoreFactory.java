@@ -13,6 +13,10 @@
 
 package org.eclipse.jetty.server.session;
 
+import java.sql.Connection;
+
+import org.eclipse.jetty.util.component.ValidationReport;
+
 /**
  * JDBCSessionDataStoreFactory
  */
@@ -29,6 +33,10 @@ public class JDBCSessionDataStoreFactory extends AbstractSessionDataStoreFactory
      */
     JDBCSessionDataStore.SessionTableSchema _schema;
 
+    int _scavengeBatchSize;
+    int _writeBatchSize;
+    int _transactionIsolation = -1;
+
     @Override
     public SessionDataStore getSessionDataStore(SessionHandler handler)
     {
@@ -37,6 +45,10 @@ public class JDBCSessionDataStoreFactory extends AbstractSessionDataStoreFactory
         ds.setSessionTableSchema(_schema);
         ds.setGracePeriodSec(getGracePeriodSec());
         ds.setSavePeriodSec(getSavePeriodSec());
+        ds.setScavengeBatchSize(_scavengeBatchSize);
+        ds.setWriteBatchSize(_writeBatchSize);
+        ds.setTransactionIsolation(_transactionIsolation);
+        configure(ds.getSessionDataSerialization());
         return ds;
     }
 
@@ -55,4 +67,60 @@ public class JDBCSessionDataStoreFactory extends AbstractSessionDataStoreFactory
     {
         _schema = schema;
     }
+
+    /**
+     * @param scavengeBatchSize the max number of orphaned sessions deleted per transaction
+     * @see JDBCSessionDataStore#setScavengeBatchSize(int)
+     */
+    public void setScavengeBatchSize(int scavengeBatchSize)
+    {
+        _scavengeBatchSize = scavengeBatchSize;
+    }
+
+    /**
+     * @param writeBatchSize the number of session updates written in a batch
+     * @see JDBCSessionDataStore#setWriteBatchSize(int)
+     */
+    public void setWriteBatchSize(int writeBatchSize)
+    {
+        _writeBatchSize = writeBatchSize;
+    }
+
+    /**
+     * @param transactionIsolation the isolation level of the batch transactions
+     * @see JDBCSessionDataStore#setTransactionIsolation(int)
+     */
+    public void setTransactionIsolation(int transactionIsolation)
+    {
+        _transactionIsolation = transactionIsolation;
+    }
+
+    /**
+     * Check that a connection to the database can be obtained.
+     *
+     * @param report the report to add the problems to
+     */
+    @Override
+    public void validate(ValidationReport report)
+    {
+        super.validate(report);
+        if (_adaptor == null)
+        {
+            report.addProblem(this, "No DatabaseAdaptor");
+            return;
+        }
+        try
+        {
+            _adaptor.initialize();
+            try (Connection connection = _adaptor.getConnection())
+            {
+                if (!connection.isValid(5))
+                    report.addProblem(this, "Invalid connection to " + _adaptor);
+            }
+        }
+        catch (Throwable x)
+        {
+            report.addProblem(this, "Unable to connect to " + _adaptor, x);
+        }
+    }
 }
@@ -0,0 +1,58 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.ObjectOutputStream;
+
+import org.eclipse.jetty.util.ClassLoadingObjectInputStream;
+
+/**
+ * <p>The {@code java} {@link SessionDataCodec}, that serializes the attributes with
+ * {@link SessionData#serializeAttributes(SessionData, ObjectOutputStream)}, so
+ * attribute values must be {@link java.io.Serializable}.</p>
+ */
+public class JavaSessionDataCodec extends SessionDataCodec
+{
+    public JavaSessionDataCodec()
+    {
+        super("java");
+    }
+
+    @Override
+    public byte[] encode(SessionData data) throws IOException
+    {
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+        try (ObjectOutputStream out = new ObjectOutputStream(bytes))
+        {
+            SessionData.serializeAttributes(data, out);
+        }
+        return bytes.toByteArray();
+    }
+
+    @Override
+    public void decode(SessionData data, byte[] bytes) throws IOException
+    {
+        try (ClassLoadingObjectInputStream in = new ClassLoadingObjectInputStream(new ByteArrayInputStream(bytes)))
+        {
+            SessionData.deserializeAttributes(data, in);
+        }
+        catch (ClassNotFoundException x)
+        {
+            throw new IOException(x);
+        }
+    }
+}
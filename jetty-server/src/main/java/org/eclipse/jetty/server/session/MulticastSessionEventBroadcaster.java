@@ -0,0 +1,162 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.net.DatagramPacket;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.MulticastSocket;
+import java.net.NetworkInterface;
+import java.net.SocketException;
+import java.util.Arrays;
+
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * MulticastSessionEventBroadcaster
+ *
+ * A {@link SessionEventBroadcaster} that exchanges session events with
+ * the other nodes of the cluster using UDP IP multicast, without the need
+ * of any message broker.
+ * Delivery is not guaranteed, which is acceptable since events are only
+ * used to evict cached sessions earlier than the eviction policy would.
+ */
+@ManagedObject
+public class MulticastSessionEventBroadcaster extends AbstractSessionEventBroadcaster
+{
+    private static final Logger LOG = LoggerFactory.getLogger(MulticastSessionEventBroadcaster.class);
+    public static final String DEFAULT_GROUP = "239.255.43.21";
+    public static final int DEFAULT_PORT = 45700;
+    private static final int MAX_PACKET_SIZE = 65507;
+
+    private String _group = DEFAULT_GROUP;
+    private int _port = DEFAULT_PORT;
+    private int _timeToLive = 1;
+    private String _networkInterface;
+    private MulticastSocket _socket;
+    private InetSocketAddress _groupAddress;
+    private Thread _receiver;
+
+    @ManagedAttribute(value = "The multicast group address", readonly = true)
+    public String getGroup()
+    {
+        return _group;
+    }
+
+    public void setGroup(String group)
+    {
+        _group = group;
+    }
+
+    @ManagedAttribute(value = "The multicast port", readonly = true)
+    public int getPort()
+    {
+        return _port;
+    }
+
+    public void setPort(int port)
+    {
+        _port = port;
+    }
+
+    @ManagedAttribute(value = "The time to live of the multicast packets", readonly = true)
+    public int getTimeToLive()
+    {
+        return _timeToLive;
+    }
+
+    public void setTimeToLive(int timeToLive)
+    {
+        _timeToLive = timeToLive;
+    }
+
+    @ManagedAttribute(value = "The name of the network interface, or null for the default one", readonly = true)
+    public String getNetworkInterface()
+    {
+        return _networkInterface;
+    }
+
+    public void setNetworkInterface(String networkInterface)
+    {
+        _networkInterface = networkInterface;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        _groupAddress = new InetSocketAddress(InetAddress.getByName(_group), _port);
+        NetworkInterface networkInterface = StringUtil.isBlank(_networkInterface) ? null : NetworkInterface.getByName(_networkInterface);
+        _socket = new MulticastSocket(_port);
+        _socket.setTimeToLive(_timeToLive);
+        if (networkInterface != null)
+            _socket.setNetworkInterface(networkInterface);
+        _socket.joinGroup(_groupAddress, networkInterface);
+        _receiver = new Thread(this::receive, String.format("SessionEvents-%s:%d", _group, _port));
+        _receiver.setDaemon(true);
+        _receiver.start();
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        _socket.close();
+        _receiver.join();
+        _receiver = null;
+        _socket = null;
+    }
+
+    @Override
+    protected void send(byte[] bytes) throws Exception
+    {
+        if (bytes.length > MAX_PACKET_SIZE)
+            throw new IllegalArgumentException("Session event too large " + bytes.length);
+        _socket.send(new DatagramPacket(bytes, bytes.length, _groupAddress));
+    }
+
+    private void receive()
+    {
+        byte[] buffer = new byte[MAX_PACKET_SIZE];
+        while (true)
+        {
+            try
+            {
+                DatagramPacket packet = new DatagramPacket(buffer, buffer.length);
+                _socket.receive(packet);
+                receive(Arrays.copyOfRange(packet.getData(), packet.getOffset(), packet.getOffset() + packet.getLength()));
+            }
+            catch (SocketException x)
+            {
+                if (_socket.isClosed())
+                    return;
+                LOG.warn("Unable to receive session event", x);
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Unable to receive session event", x);
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s:%d]", getClass().getSimpleName(), hashCode(), _group, _port);
+    }
+}
@@ -0,0 +1,124 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.ServiceLoader;
+
+import org.eclipse.jetty.util.TypeUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A format used to serialize the attributes of a {@link SessionData}.</p>
+ * <p>Implementations are discovered with the {@link ServiceLoader}, so that
+ * alternative formats such as Kryo or JSON become available by just adding
+ * their jar to the server class-path or module-path.
+ * The {@link JavaSessionDataCodec java} codec, which uses Java serialization,
+ * is always available.</p>
+ *
+ * @see SessionDataSerialization
+ */
+public abstract class SessionDataCodec
+{
+    private static final Logger LOG = LoggerFactory.getLogger(SessionDataCodec.class);
+    private static final List<SessionDataCodec> __codecs = new ArrayList<>();
+
+    static
+    {
+        Map<String, SessionDataCodec> codecs = new HashMap<>();
+        SessionDataCodec java = new JavaSessionDataCodec();
+        codecs.put(java.getName(), java);
+        __codecs.add(java);
+        TypeUtil.serviceProviderStream(ServiceLoader.load(SessionDataCodec.class)).forEach(provider ->
+        {
+            try
+            {
+                SessionDataCodec codec = provider.get();
+                if (codecs.putIfAbsent(codec.getName(), codec) == null)
+                    __codecs.add(codec);
+                else
+                    LOG.warn("multiple SessionDataCodecs for {}", codec.getName());
+            }
+            catch (Error | RuntimeException e)
+            {
+                LOG.debug("Unable to add SessionDataCodec", e);
+            }
+        });
+        LOG.debug("SessionDataCodecs loaded: {}", __codecs);
+    }
+
+    /**
+     * @return the available codecs, {@code java} first
+     */
+    public static List<SessionDataCodec> getSessionDataCodecs()
+    {
+        return List.copyOf(__codecs);
+    }
+
+    /**
+     * @param name the codec name
+     * @return the available codec with the given name, or {@code null}
+     */
+    public static SessionDataCodec getSessionDataCodec(String name)
+    {
+        for (SessionDataCodec codec : __codecs)
+        {
+            if (codec.getName().equalsIgnoreCase(name))
+                return codec;
+        }
+        return null;
+    }
+
+    private final String name;
+
+    protected SessionDataCodec(String name)
+    {
+        this.name = name;
+    }
+
+    /**
+     * @return the codec name, such as {@code java}, recorded with the serialized bytes
+     */
+    public String getName()
+    {
+        return name;
+    }
+
+    /**
+     * @param data the session data whose attributes are encoded
+     * @return the encoded attributes
+     * @throws IOException if the attributes cannot be encoded
+     */
+    public abstract byte[] encode(SessionData data) throws IOException;
+
+    /**
+     * <p>Decodes the given bytes and puts the attributes into the given session data.</p>
+     *
+     * @param data the session data to put the decoded attributes into
+     * @param bytes the encoded attributes
+     * @throws IOException if the attributes cannot be decoded
+     */
+    public abstract void decode(SessionData data, byte[] bytes) throws IOException;
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), getName());
+    }
+}
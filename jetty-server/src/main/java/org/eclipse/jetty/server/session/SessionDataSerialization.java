@@ -0,0 +1,414 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.DataInputStream;
+import java.io.DataOutputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.io.PushbackInputStream;
+import java.nio.ByteBuffer;
+import java.util.concurrent.atomic.LongAdder;
+
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.ClassLoadingObjectInputStream;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.statistic.SampleStatistic;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Serializes the attributes of a {@link SessionData} for a {@link SessionDataStore},
+ * using a {@link SessionDataCodec} for the format and, optionally, a
+ * {@link ContentCodec} such as {@code gzip} or {@code zstd} for compression.</p>
+ * <p>Attributes are compressed only when their encoded size is at least the
+ * {@link #setCompressionThreshold(int) compression threshold}, and only if
+ * compression actually reduces their size.</p>
+ * <p>Serialized bytes other than plain Java serialization are prefixed by a header
+ * that records the format version, the codec and the compression, so that sessions
+ * can always be read back even after the configuration has changed.
+ * Uncompressed attributes serialized with the {@code java} codec are written without
+ * header, and are therefore also readable by nodes that do not support codecs:
+ * a cluster may be upgraded node by node, and codecs or compression enabled once
+ * all the nodes have been upgraded.</p>
+ * <p>The sizes of the serialized sessions are recorded to help diagnose bloated
+ * sessions, and a warning is logged for sessions larger than the
+ * {@link #setWarnSize(int) warn size}.</p>
+ */
+@ManagedObject("Session data serialization")
+public class SessionDataSerialization
+{
+    public static final int VERSION = 1;
+    public static final int DEFAULT_COMPRESSION_THRESHOLD = 1024;
+    private static final Logger LOG = LoggerFactory.getLogger(SessionDataSerialization.class);
+    private static final int MAGIC = 0x4A53;
+
+    private final AutoLock _lock = new AutoLock();
+    private final SampleStatistic _serializedSizes = new SampleStatistic();
+    private final SampleStatistic _storedSizes = new SampleStatistic();
+    private final LongAdder _compressed = new LongAdder();
+    private SessionDataCodec _codec = SessionDataCodec.getSessionDataCodec("java");
+    private ContentCodec _compression;
+    private int _compressionLevel = -1;
+    private int _compressionThreshold = DEFAULT_COMPRESSION_THRESHOLD;
+    private int _warnSize = -1;
+    private String _largestSessionId;
+    private long _largestSessionSize;
+
+    /**
+     * @return the codec used to serialize the attributes
+     */
+    public SessionDataCodec getCodec()
+    {
+        return _codec;
+    }
+
+    /**
+     * @param codec the codec used to serialize the attributes
+     */
+    public void setCodec(SessionDataCodec codec)
+    {
+        _codec = codec == null ? SessionDataCodec.getSessionDataCodec("java") : codec;
+    }
+
+    @ManagedAttribute("The name of the codec used to serialize the attributes")
+    public String getCodecName()
+    {
+        return _codec.getName();
+    }
+
+    /**
+     * @param name the name of an available {@link SessionDataCodec}, or {@code null} for {@code java}
+     * @throws IllegalArgumentException if no codec with the given name is available
+     */
+    public void setCodecName(String name)
+    {
+        if (StringUtil.isBlank(name))
+        {
+            setCodec(null);
+            return;
+        }
+        SessionDataCodec codec = SessionDataCodec.getSessionDataCodec(name.trim());
+        if (codec == null)
+            throw new IllegalArgumentException("Unknown session data codec " + name);
+        setCodec(codec);
+    }
+
+    @ManagedAttribute("The content coding used to compress the attributes, or null for no compression")
+    public String getCompression()
+    {
+        return _compression == null ? null : _compression.getEncoding();
+    }
+
+    /**
+     * @param encoding the content coding of an available {@link ContentCodec}
+     * used to compress the attributes, or {@code null} for no compression
+     * @throws IllegalArgumentException if no codec with the given content coding is available
+     */
+    public void setCompression(String encoding)
+    {
+        if (StringUtil.isBlank(encoding))
+        {
+            _compression = null;
+            return;
+        }
+        ContentCodec codec = ContentCodec.getContentCodec(encoding.trim());
+        if (codec == null || !codec.isEncodingSupported() || !codec.isDecodingSupported())
+            throw new IllegalArgumentException("Unsupported session data compression " + encoding);
+        _compression = codec;
+    }
+
+    @ManagedAttribute("The compression level, or -1 for the default level")
+    public int getCompressionLevel()
+    {
+        return _compressionLevel;
+    }
+
+    public void setCompressionLevel(int compressionLevel)
+    {
+        _compressionLevel = compressionLevel;
+    }
+
+    @ManagedAttribute("The min encoded size in bytes of the attributes to compress them")
+    public int getCompressionThreshold()
+    {
+        return _compressionThreshold;
+    }
+
+    public void setCompressionThreshold(int compressionThreshold)
+    {
+        _compressionThreshold = compressionThreshold;
+    }
+
+    @ManagedAttribute("The encoded size in bytes above which a warning is logged, or -1 to never warn")
+    public int getWarnSize()
+    {
+        return _warnSize;
+    }
+
+    public void setWarnSize(int warnSize)
+    {
+        _warnSize = warnSize;
+    }
+
+    @ManagedAttribute("The number of serialized sessions")
+    public long getSerializedCount()
+    {
+        return _serializedSizes.getCount();
+    }
+
+    @ManagedAttribute("The number of serialized sessions that have been compressed")
+    public long getCompressedCount()
+    {
+        return _compressed.sum();
+    }
+
+    @ManagedAttribute("The max encoded size in bytes of the serialized sessions")
+    public long getSerializedSizeMax()
+    {
+        return _serializedSizes.getMax();
+    }
+
+    @ManagedAttribute("The mean encoded size in bytes of the serialized sessions")
+    public double getSerializedSizeMean()
+    {
+        return _serializedSizes.getMean();
+    }
+
+    @ManagedAttribute("The total encoded size in bytes of the serialized sessions")
+    public long getSerializedSizeTotal()
+    {
+        return _serializedSizes.getTotal();
+    }
+
+    @ManagedAttribute("The total stored size in bytes of the serialized sessions, after compression")
+    public long getStoredSizeTotal()
+    {
+        return _storedSizes.getTotal();
+    }
+
+    @ManagedAttribute("The id of the largest serialized session")
+    public String getLargestSessionId()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _largestSessionId;
+        }
+    }
+
+    @ManagedOperation(value = "Resets the serialization statistics", impact = "ACTION")
+    public void reset()
+    {
+        _serializedSizes.reset();
+        _storedSizes.reset();
+        _compressed.reset();
+        try (AutoLock l = _lock.lock())
+        {
+            _largestSessionId = null;
+            _largestSessionSize = 0;
+        }
+    }
+
+    /**
+     * @param data the session data whose attributes are serialized
+     * @return the serialized attributes
+     * @throws IOException if the attributes cannot be serialized
+     */
+    public byte[] serializeAttributes(SessionData data) throws IOException
+    {
+        SessionDataCodec codec = _codec;
+        ContentCodec compression = _compression;
+
+        byte[] encoded = codec.encode(data);
+        byte[] payload = encoded;
+        if (compression != null && encoded.length >= _compressionThreshold)
+        {
+            byte[] compressed = compress(compression, encoded);
+            if (compressed.length < encoded.length)
+                payload = compressed;
+            else
+                compression = null;
+        }
+        else
+        {
+            compression = null;
+        }
+
+        byte[] result;
+        if (compression == null && codec instanceof JavaSessionDataCodec)
+        {
+            result = encoded;
+        }
+        else
+        {
+            ByteArrayOutputStream bytes = new ByteArrayOutputStream(payload.length + 32);
+            DataOutputStream out = new DataOutputStream(bytes);
+            out.writeShort(MAGIC);
+            out.writeByte(VERSION);
+            out.writeUTF(codec.getName());
+            out.writeUTF(compression == null ? "" : compression.getEncoding());
+            out.writeInt(payload.length);
+            out.write(payload);
+            out.flush();
+            result = bytes.toByteArray();
+        }
+
+        onSerialized(data, encoded.length, result.length, compression != null);
+        return result;
+    }
+
+    /**
+     * @param data the session data whose attributes are serialized
+     * @param out the stream to write the serialized attributes to
+     * @throws IOException if the attributes cannot be serialized
+     */
+    public void serializeAttributes(SessionData data, OutputStream out) throws IOException
+    {
+        out.write(serializeAttributes(data));
+    }
+
+    /**
+     * @param data the session data to put the deserialized attributes into
+     * @param bytes the serialized attributes
+     * @throws IOException if the attributes cannot be deserialized
+     */
+    public void deserializeAttributes(SessionData data, byte[] bytes) throws IOException
+    {
+        deserializeAttributes(data, new ByteArrayInputStream(bytes));
+    }
+
+    /**
+     * <p>Deserializes the attributes read from the given stream, that may have been
+     * serialized with any version, codec or compression, and also without header.</p>
+     *
+     * @param data the session data to put the deserialized attributes into
+     * @param input the stream to read the serialized attributes from
+     * @throws IOException if the attributes cannot be deserialized
+     */
+    public void deserializeAttributes(SessionData data, InputStream input) throws IOException
+    {
+        PushbackInputStream in = new PushbackInputStream(input, 2);
+        int b1 = in.read();
+        int b2 = in.read();
+        if (b1 == (MAGIC >>> 8) && b2 == (MAGIC & 0xFF))
+        {
+            DataInputStream din = new DataInputStream(in);
+            int version = din.readUnsignedByte();
+            if (version > VERSION)
+                throw new IOException("Unsupported session data version " + version);
+            String codecName = din.readUTF();
+            String encoding = din.readUTF();
+            byte[] payload = new byte[din.readInt()];
+            din.readFully(payload);
+
+            SessionDataCodec codec = _codec;
+            if (!codec.getName().equalsIgnoreCase(codecName))
+                codec = SessionDataCodec.getSessionDataCodec(codecName);
+            if (codec == null)
+                throw new IOException("Unknown session data codec " + codecName);
+            if (!encoding.isEmpty())
+            {
+                ContentCodec compression = ContentCodec.getContentCodec(encoding);
+                if (compression == null || !compression.isDecodingSupported())
+                    throw new IOException("Unsupported session data compression " + encoding);
+                payload = decompress(compression, payload);
+            }
+            if (LOG.isDebugEnabled())
+                LOG.debug("Deserializing {} version={} codec={} compression={}", data.getId(), version, codecName, encoding);
+            codec.decode(data, payload);
+        }
+        else
+        {
+            if (b2 >= 0)
+                in.unread(b2);
+            if (b1 >= 0)
+                in.unread(b1);
+            try
+            {
+                SessionData.deserializeAttributes(data, new ClassLoadingObjectInputStream(in));
+            }
+            catch (ClassNotFoundException x)
+            {
+                throw new IOException(x);
+            }
+        }
+    }
+
+    private byte[] compress(ContentCodec compression, byte[] bytes) throws IOException
+    {
+        ByteArrayOutputStream result = new ByteArrayOutputStream(bytes.length / 2);
+        try (OutputStream out = compression.newEncoder(result, _compressionLevel))
+        {
+            out.write(bytes);
+        }
+        return result.toByteArray();
+    }
+
+    private byte[] decompress(ContentCodec compression, byte[] bytes) throws IOException
+    {
+        ByteArrayOutputStream result = new ByteArrayOutputStream(bytes.length * 2);
+        ContentCodec.Decoder decoder = compression.newDecoder(null);
+        try
+        {
+            ByteBuffer buffer = ByteBuffer.wrap(bytes);
+            while (buffer.hasRemaining())
+            {
+                ByteBuffer decoded = decoder.decode(buffer);
+                BufferUtil.writeTo(decoded, result);
+                decoder.release(decoded);
+            }
+        }
+        finally
+        {
+            decoder.destroy();
+        }
+        return result.toByteArray();
+    }
+
+    private void onSerialized(SessionData data, int encodedSize, int storedSize, boolean compressed)
+    {
+        _serializedSizes.record(encodedSize);
+        _storedSizes.record(storedSize);
+        if (compressed)
+            _compressed.increment();
+        try (AutoLock l = _lock.lock())
+        {
+            if (encodedSize > _largestSessionSize)
+            {
+                _largestSessionSize = encodedSize;
+                _largestSessionId = data.getId();
+            }
+        }
+
+        int warnSize = _warnSize;
+        if (warnSize >= 0 && encodedSize > warnSize)
+            LOG.warn("Session {} serialized to {} bytes, exceeding {} bytes, attributes {}", data.getId(), encodedSize, warnSize, data.getKeys());
+        else if (LOG.isDebugEnabled())
+            LOG.debug("Serialized {} to {}/{} bytes, compressed={}", data.getId(), encodedSize, storedSize, compressed);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[codec=%s,compression=%s,threshold=%d]", getClass().getSimpleName(), hashCode(), getCodecName(), getCompression(), getCompressionThreshold());
+    }
+}
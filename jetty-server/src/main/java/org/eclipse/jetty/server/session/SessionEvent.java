@@ -0,0 +1,123 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.util.Objects;
+import java.util.Set;
+
+/**
+ * SessionEvent
+ *
+ * A session lifecycle event published by a node of a cluster through a
+ * {@link SessionEventBroadcaster}, so that the other nodes can evict their
+ * cached copy of the session without waiting for the next load, or can
+ * load a session handed off by a node that is shutting down.
+ */
+public class SessionEvent
+{
+    /**
+     * The types of session events.
+     */
+    public enum Type
+    {
+        /**
+         * A session has been created.
+         */
+        CREATED,
+        /**
+         * A session has been invalidated, either explicitly or because it expired.
+         */
+        INVALIDATED,
+        /**
+         * One or more attributes of a session have been set or removed.
+         */
+        ATTRIBUTES_CHANGED,
+        /**
+         * A session has been stored by a node that is shutting down, and can be
+         * loaded by another node before the next request for it arrives.
+         */
+        HANDOFF
+    }
+
+    private final Type _type;
+    private final String _workerName;
+    private final String _vhost;
+    private final String _canonicalContextPath;
+    private final String _id;
+    private final Set<String> _attributeNames;
+
+    /**
+     * @param type the type of the event
+     * @param workerName the name of the node that published the event, may be null
+     * @param vhost the canonical virtual host of the context of the session
+     * @param canonicalContextPath the canonical context path of the session
+     * @param id the session id
+     * @param attributeNames the names of the changed attributes, empty unless the type is {@link Type#ATTRIBUTES_CHANGED}
+     */
+    public SessionEvent(Type type, String workerName, String vhost, String canonicalContextPath, String id, Set<String> attributeNames)
+    {
+        _type = Objects.requireNonNull(type);
+        _workerName = workerName;
+        _vhost = Objects.requireNonNull(vhost);
+        _canonicalContextPath = Objects.requireNonNull(canonicalContextPath);
+        _id = Objects.requireNonNull(id);
+        _attributeNames = attributeNames == null ? Set.of() : Set.copyOf(attributeNames);
+    }
+
+    public Type getType()
+    {
+        return _type;
+    }
+
+    public String getWorkerName()
+    {
+        return _workerName;
+    }
+
+    public String getVhost()
+    {
+        return _vhost;
+    }
+
+    public String getCanonicalContextPath()
+    {
+        return _canonicalContextPath;
+    }
+
+    public String getId()
+    {
+        return _id;
+    }
+
+    public Set<String> getAttributeNames()
+    {
+        return _attributeNames;
+    }
+
+    /**
+     * @param context the session context
+     * @return whether this event refers to a session of the given context
+     */
+    public boolean isFor(SessionContext context)
+    {
+        return _vhost.equals(context.getVhost()) && _canonicalContextPath.equals(context.getCanonicalContextPath());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,id=%s,context=%s_%s,node=%s,attributes=%s]",
+            getClass().getSimpleName(), hashCode(), _type, _id, _canonicalContextPath, _vhost, _workerName, _attributeNames);
+    }
+}
@@ -0,0 +1,67 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.util.EventListener;
+
+/**
+ * SessionEventBroadcaster
+ *
+ * Publishes {@link SessionEvent}s to the other nodes of a cluster, and
+ * notifies {@link Listener}s of the events received from the other nodes.
+ * Events published by this broadcaster are never notified to its own listeners.
+ *
+ * A broadcaster is typically added as a bean to the Server, where it is
+ * found and shared by all the SessionHandlers, each of them only reacting to
+ * the events of its own context: sessions that are invalidated or that have
+ * their attributes changed on another node are evicted from the local
+ * {@link SessionCache}, so that the next request loads them afresh from the
+ * {@link SessionDataStore}.
+ *
+ * Implementations provide the transport, for example IP multicast as in
+ * {@link MulticastSessionEventBroadcaster}, or a message broker such as a
+ * Redis pub/sub channel, a JGroups cluster or a Kafka topic, usually by
+ * extending {@link AbstractSessionEventBroadcaster}.
+ */
+public interface SessionEventBroadcaster
+{
+    /**
+     * Publishes the given event to the other nodes.
+     * Implementations should not block the calling thread.
+     *
+     * @param event the event to publish
+     */
+    void publish(SessionEvent event);
+
+    /**
+     * @param listener the listener of the events received from the other nodes
+     */
+    void addListener(Listener listener);
+
+    /**
+     * @param listener the listener to remove
+     */
+    void removeListener(Listener listener);
+
+    /**
+     * Listener of the session events received from other nodes.
+     */
+    interface Listener extends EventListener
+    {
+        /**
+         * @param event the event received from another node
+         */
+        void onSessionEvent(SessionEvent event);
+    }
+}
@@ -14,17 +14,25 @@
 package org.eclipse.jetty.server.session;
 
 import java.io.IOException;
+import java.security.Principal;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
 import java.util.Arrays;
 import java.util.Collections;
+import java.util.Deque;
 import java.util.EnumSet;
 import java.util.Enumeration;
 import java.util.EventListener;
+import java.util.HashMap;
 import java.util.HashSet;
 import java.util.List;
+import java.util.Map;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
 import java.util.stream.Collectors;
 import javax.servlet.DispatcherType;
 import javax.servlet.ServletException;
@@ -45,14 +53,17 @@ import org.eclipse.jetty.http.BadMessageException;
 import org.eclipse.jetty.http.HttpCookie;
 import org.eclipse.jetty.http.Syntax;
 import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Response;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.SessionIdManager;
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.server.handler.ScopedHandler;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.Graceful;
 import org.eclipse.jetty.util.statistic.CounterStatistic;
 import org.eclipse.jetty.util.statistic.SampleStatistic;
 import org.eclipse.jetty.util.thread.AutoLock;
@@ -65,9 +76,18 @@ import static java.lang.Math.round;
 
 /**
  * SessionHandler.
+ *
+ * On graceful shutdown of the Server, the cached sessions are drained
+ * to the {@link SessionDataStore}, see {@link #drain(long, TimeUnit)}.
+ *
+ * When a request is authenticated, the session id is renewed to protect
+ * against session fixation, see {@link #renewSessionOnAuthentication(HttpServletRequest, HttpServletResponse)},
+ * and the number of concurrent sessions of the user may be limited, see
+ * {@link #registerAuthenticatedSession(HttpServletRequest, Principal)}.
+ * These security actions are notified to {@link SessionSecurityListener}s.
  */
 @ManagedObject
-public class SessionHandler extends ScopedHandler
+public class SessionHandler extends ScopedHandler implements Graceful
 {
     private static final Logger LOG = LoggerFactory.getLogger(SessionHandler.class);
 
@@ -190,6 +210,20 @@ public class SessionHandler extends ScopedHandler
     protected Scheduler _scheduler;
     protected boolean _ownScheduler = false;
 
+    protected SessionEventBroadcaster _sessionEventBroadcaster;
+    private final SessionEventBroadcaster.Listener _sessionEventListener = this::onSessionEvent;
+    private final Map<String, Set<String>> _changedAttributes = new ConcurrentHashMap<>();
+    private long _drainTimeout = 5000;
+    private boolean _handoffOnDrain;
+    private volatile boolean _shutdown;
+    private final List<SessionSecurityListener> _sessionSecurityListeners = new CopyOnWriteArrayList<>();
+    private final AutoLock _userSessionsLock = new AutoLock();
+    private final Map<String, Deque<String>> _userSessions = new HashMap<>();
+    private final Map<String, String> _sessionUsers = new HashMap<>();
+    private int _maxSessionsPerUser = -1;
+    private ConcurrentSessionPolicy _concurrentSessionPolicy = ConcurrentSessionPolicy.INVALIDATE_OLDEST;
+    private UserKeyExtractor _userKeyExtractor = (request, principal) -> principal == null ? null : principal.getName();
+
     /**
      * Constructor.
      */
@@ -268,6 +302,8 @@ public class SessionHandler extends ScopedHandler
                 _sessionListeners.add((HttpSessionListener)listener);
             if (listener instanceof HttpSessionIdListener)
                 _sessionIdListeners.add((HttpSessionIdListener)listener);
+            if (listener instanceof SessionSecurityListener)
+                _sessionSecurityListeners.add((SessionSecurityListener)listener);
             return true;
         }
         return false;
@@ -284,6 +320,8 @@ public class SessionHandler extends ScopedHandler
         if (session == null)
             return;
 
+        unregisterAuthenticatedSession(session.getId());
+
         if (_sessionListeners != null)
         {
             //We annoint the calling thread with
@@ -328,6 +366,18 @@ public class SessionHandler extends ScopedHandler
 
     protected void callSessionIdListeners(Session session, String oldId)
     {
+        try (AutoLock l = _userSessionsLock.lock())
+        {
+            String userKey = _sessionUsers.remove(oldId);
+            if (userKey != null)
+            {
+                _sessionUsers.put(session.getId(), userKey);
+                Deque<String> ids = _userSessions.get(userKey);
+                ids.remove(oldId);
+                ids.add(session.getId());
+            }
+        }
+
         //inform the listeners
         if (!_sessionIdListeners.isEmpty())
         {
@@ -361,6 +411,10 @@ public class SessionHandler extends ScopedHandler
         {
             LOG.warn("Unable to release Session {}", s, e);
         }
+
+        Set<String> changed = _changedAttributes.remove(s.getId());
+        if (changed != null)
+            publishSessionEvent(SessionEvent.Type.ATTRIBUTES_CHANGED, s.getId(), changed);
     }
 
     /**
@@ -439,6 +493,9 @@ public class SessionHandler extends ScopedHandler
                 addBean(_sessionIdManager, false);
             }
 
+            if (_sessionEventBroadcaster == null)
+                _sessionEventBroadcaster = server.getBean(SessionEventBroadcaster.class);
+
             _scheduler = server.getBean(Scheduler.class);
             if (_scheduler == null)
             {
@@ -482,12 +539,19 @@ public class SessionHandler extends ScopedHandler
 
         _sessionContext = new SessionContext(_sessionIdManager.getWorkerName(), _context);
         _sessionCache.initialize(_sessionContext);
+        _shutdown = false;
+        if (_sessionEventBroadcaster != null)
+            _sessionEventBroadcaster.addListener(_sessionEventListener);
         super.doStart();
     }
 
     @Override
     protected void doStop() throws Exception
     {
+        if (_sessionEventBroadcaster != null)
+            _sessionEventBroadcaster.removeListener(_sessionEventListener);
+        _changedAttributes.clear();
+
         // Destroy sessions before destroying servlets/filters see JETTY-1266
         shutdownSessions();
         _sessionCache.stop();
@@ -755,6 +819,7 @@ public class SessionHandler extends ScopedHandler
                 session.setAttribute(Session.SESSION_CREATED_SECURE, Boolean.TRUE);
 
             callSessionCreatedListeners(session);
+            publishSessionEvent(SessionEvent.Type.CREATED, id, null);
 
             return session;
         }
@@ -776,6 +841,8 @@ public class SessionHandler extends ScopedHandler
                 _sessionListeners.remove(listener);
             if (listener instanceof HttpSessionIdListener)
                 _sessionIdListeners.remove(listener);
+            if (listener instanceof SessionSecurityListener)
+                _sessionSecurityListeners.remove(listener);
             return true;
         }
         return false;
@@ -956,6 +1023,180 @@ public class SessionHandler extends ScopedHandler
         _sessionCache = cache;
     }
 
+    /**
+     * @return the max time in milliseconds to wait for sessions in use by requests
+     * to be released when draining on graceful shutdown
+     */
+    @ManagedAttribute("max time in ms to wait for sessions in use when draining on graceful shutdown")
+    public long getDrainTimeout()
+    {
+        return _drainTimeout;
+    }
+
+    /**
+     * Set the max time to wait for the sessions in use by requests to be released
+     * when draining on graceful shutdown. It should be less than the
+     * {@link Server#getStopTimeout() stop timeout} of the Server.
+     *
+     * @param drainTimeout the drain timeout in milliseconds
+     */
+    public void setDrainTimeout(long drainTimeout)
+    {
+        _drainTimeout = drainTimeout;
+    }
+
+    /**
+     * @return true if the other nodes are notified of the drained sessions
+     */
+    @ManagedAttribute("whether drained sessions are handed off to the other nodes")
+    public boolean isHandoffOnDrain()
+    {
+        return _handoffOnDrain;
+    }
+
+    /**
+     * Set whether a {@link SessionEvent.Type#HANDOFF} event is published for each
+     * drained session, so that the other nodes load it before the next request
+     * for it arrives. Requires a {@link SessionEventBroadcaster}.
+     *
+     * @param handoffOnDrain true to notify the other nodes of the drained sessions
+     */
+    public void setHandoffOnDrain(boolean handoffOnDrain)
+    {
+        _handoffOnDrain = handoffOnDrain;
+    }
+
+    @Override
+    public CompletableFuture<Void> shutdown()
+    {
+        _shutdown = true;
+        return Graceful.shutdown(() -> drain(getDrainTimeout(), TimeUnit.MILLISECONDS));
+    }
+
+    @Override
+    public boolean isShutdown()
+    {
+        return _shutdown;
+    }
+
+    /**
+     * Drain the sessions of this handler: the cached sessions are passivated,
+     * stored and removed from the session cache as soon as they are not in use
+     * by a request, rather than waiting for the eviction timers or for this
+     * handler to stop. If {@link #isHandoffOnDrain()} is true, the other nodes
+     * are notified of each drained session.
+     *
+     * Sessions still in use when the timeout expires are left in the cache, and
+     * are dealt with by {@link SessionCache#shutdown()} when this handler stops.
+     * Sessions are not drained if the cache invalidates them on shutdown.
+     *
+     * @param timeout the max time to wait for sessions in use to be released
+     * @param unit the unit of the timeout
+     * @return the number of drained sessions
+     * @throws InterruptedException if interrupted while waiting for sessions in use
+     */
+    public int drain(long timeout, TimeUnit unit) throws InterruptedException
+    {
+        if (!(_sessionCache instanceof AbstractSessionCache))
+            return 0;
+        AbstractSessionCache cache = (AbstractSessionCache)_sessionCache;
+        if (cache.isInvalidateOnShutdown())
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Not draining {}, sessions are invalidated on shutdown", this);
+            return 0;
+        }
+
+        long end = NanoTime.now() + unit.toNanos(timeout);
+        int drained = 0;
+        while (true)
+        {
+            Set<String> ids = cache.drain();
+            drained += ids.size();
+            if (isHandoffOnDrain())
+                ids.forEach(id -> publishSessionEvent(SessionEvent.Type.HANDOFF, id, null));
+            if (cache.getCachedSessionIds().isEmpty() || NanoTime.until(end) <= 0)
+                break;
+            Thread.sleep(Math.min(100, Math.max(1, NanoTime.millisUntil(end))));
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("Drained {} sessions from {}", drained, this);
+        return drained;
+    }
+
+    /**
+     * @return the broadcaster of session events to the other nodes, or null
+     */
+    public SessionEventBroadcaster getSessionEventBroadcaster()
+    {
+        return _sessionEventBroadcaster;
+    }
+
+    /**
+     * Set the broadcaster of session events to the other nodes.
+     * If not set, a {@link SessionEventBroadcaster} bean of the Server is used, if any.
+     *
+     * @param broadcaster the broadcaster of session events
+     */
+    public void setSessionEventBroadcaster(SessionEventBroadcaster broadcaster)
+    {
+        if (isStarted())
+            throw new IllegalStateException("Running");
+        _sessionEventBroadcaster = broadcaster;
+    }
+
+    protected void publishSessionEvent(SessionEvent.Type type, String id, Set<String> attributeNames)
+    {
+        if (_sessionEventBroadcaster == null || _sessionContext == null)
+            return;
+        _sessionEventBroadcaster.publish(new SessionEvent(type, _sessionContext.getWorkerName(),
+            _sessionContext.getVhost(), _sessionContext.getCanonicalContextPath(), id, attributeNames));
+    }
+
+    /**
+     * Called when a session event is received from another node.
+     * Sessions of this context that have been invalidated or
+     * that have changed on the other node are evicted from the
+     * session cache, unless they are in use by a request.
+     * Sessions handed off by a node that is shutting down are
+     * loaded into the session cache.
+     *
+     * @param event the session event
+     */
+    protected void onSessionEvent(SessionEvent event)
+    {
+        if (_sessionContext == null || !event.isFor(_sessionContext))
+            return;
+
+        switch (event.getType())
+        {
+            case INVALIDATED:
+            case ATTRIBUTES_CHANGED:
+                if (_sessionCache instanceof AbstractSessionCache)
+                    ((AbstractSessionCache)_sessionCache).evict(event.getId());
+                break;
+            case HANDOFF:
+                if (_sessionCache instanceof AbstractSessionCache && !isShutdown())
+                    getServer().getThreadPool().execute(() -> prewarm(event.getId()));
+                break;
+            default:
+                break;
+        }
+    }
+
+    private void prewarm(String id)
+    {
+        try
+        {
+            ((AbstractSessionCache)_sessionCache).prewarm(id);
+        }
+        catch (Exception e)
+        {
+            LOG.warn("Unable to load handed off session {}", id, e);
+        }
+    }
+
     /**
      * Remove session from manager
      *
@@ -1134,6 +1375,232 @@ public class SessionHandler extends ScopedHandler
         }
     }
 
+    /**
+     * Renew the id of the session of the given request, which has just been
+     * authenticated, to protect against session fixation.
+     * The id is renewed only if the session may have been seen by unauthenticated
+     * requests, that is if it was not created by, or already renewed for,
+     * a secure request.
+     *
+     * @param request the authenticated request
+     * @param response the response, whose session cookie is replaced
+     * @return the session of the request, or null if there is none
+     */
+    public HttpSession renewSessionOnAuthentication(HttpServletRequest request, HttpServletResponse response)
+    {
+        HttpSession httpSession = request.getSession(false);
+        if (!(httpSession instanceof Session))
+            return httpSession;
+
+        Session session = (Session)httpSession;
+        synchronized (session)
+        {
+            if (session.getAttribute(Session.SESSION_CREATED_SECURE) == Boolean.TRUE)
+                return session;
+            String oldId = session.getId();
+            session.renewId(request);
+            session.setAttribute(Session.SESSION_CREATED_SECURE, Boolean.TRUE);
+            if (session.isIdChanged() && (response instanceof Response))
+                ((Response)response).replaceCookie(getSessionCookie(session, request.getContextPath(), request.isSecure()));
+            if (LOG.isDebugEnabled())
+                LOG.debug("renew {}->{}", oldId, session.getId());
+            for (SessionSecurityListener listener : _sessionSecurityListeners)
+            {
+                listener.onSessionIdRenewed(request, session, oldId);
+            }
+        }
+        return session;
+    }
+
+    /**
+     * Register the session of the given request as an authenticated session
+     * of the user identified by the {@link #getUserKeyExtractor() user key},
+     * enforcing the {@link #getMaxSessionsPerUser() max sessions per user}.
+     * When the user already has the max number of sessions, either the
+     * authentication is denied or the oldest sessions of the user are
+     * invalidated, depending on the {@link #getConcurrentSessionPolicy() policy}.
+     *
+     * The sessions are counted on this node only.
+     *
+     * @param request the authenticated request
+     * @param principal the authenticated user
+     * @return false if the authentication must be denied, true otherwise
+     */
+    public boolean registerAuthenticatedSession(HttpServletRequest request, Principal principal)
+    {
+        int maxSessions = getMaxSessionsPerUser();
+        if (maxSessions <= 0)
+            return true;
+        HttpSession httpSession = request.getSession(false);
+        if (!(httpSession instanceof Session))
+            return true;
+        String userKey = _userKeyExtractor.getUserKey(request, principal);
+        if (userKey == null)
+            return true;
+
+        Session session = (Session)httpSession;
+        String id = session.getId();
+        List<String> evicted = new ArrayList<>();
+        boolean denied = false;
+        try (AutoLock l = _userSessionsLock.lock())
+        {
+            String previous = _sessionUsers.get(id);
+            if (userKey.equals(previous))
+                return true;
+
+            // The session was authenticated for another user.
+            if (previous != null)
+                unregister(id);
+
+            Deque<String> ids = _userSessions.computeIfAbsent(userKey, k -> new ArrayDeque<>());
+            if (ids.size() >= maxSessions)
+            {
+                if (getConcurrentSessionPolicy() == ConcurrentSessionPolicy.DENY_NEW)
+                {
+                    denied = true;
+                }
+                else
+                {
+                    while (ids.size() >= maxSessions)
+                    {
+                        String oldest = ids.poll();
+                        _sessionUsers.remove(oldest);
+                        evicted.add(oldest);
+                    }
+                }
+            }
+            if (!denied)
+            {
+                ids.add(id);
+                _sessionUsers.put(id, userKey);
+            }
+        }
+
+        if (denied)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Denied session {} of {}, max {} sessions", id, userKey, maxSessions);
+            for (SessionSecurityListener listener : _sessionSecurityListeners)
+            {
+                listener.onSessionDenied(request, session, userKey);
+            }
+            return false;
+        }
+
+        for (String oldest : evicted)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Evicting session {} of {}, max {} sessions", oldest, userKey, maxSessions);
+            _sessionIdManager.invalidateAll(oldest);
+            for (SessionSecurityListener listener : _sessionSecurityListeners)
+            {
+                listener.onSessionEvicted(request, oldest, userKey);
+            }
+        }
+        for (SessionSecurityListener listener : _sessionSecurityListeners)
+        {
+            listener.onSessionRegistered(request, session, userKey);
+        }
+        return true;
+    }
+
+    /**
+     * Unregister the given session from the authenticated sessions
+     * of its user, typically when the user logs out.
+     *
+     * @param session the session to unregister
+     */
+    public void unregisterAuthenticatedSession(HttpSession session)
+    {
+        unregisterAuthenticatedSession(session.getId());
+    }
+
+    private void unregisterAuthenticatedSession(String id)
+    {
+        try (AutoLock l = _userSessionsLock.lock())
+        {
+            unregister(id);
+        }
+    }
+
+    private void unregister(String id)
+    {
+        assert _userSessionsLock.isHeldByCurrentThread();
+        String userKey = _sessionUsers.remove(id);
+        if (userKey == null)
+            return;
+        Deque<String> ids = _userSessions.get(userKey);
+        ids.remove(id);
+        if (ids.isEmpty())
+            _userSessions.remove(userKey);
+    }
+
+    /**
+     * @param userKey the key of the user
+     * @return the number of authenticated sessions of the user on this node
+     */
+    public int getSessionsPerUser(String userKey)
+    {
+        try (AutoLock l = _userSessionsLock.lock())
+        {
+            Deque<String> ids = _userSessions.get(userKey);
+            return ids == null ? 0 : ids.size();
+        }
+    }
+
+    /**
+     * @return the max number of authenticated sessions per user, or a non-positive value for no limit
+     */
+    @ManagedAttribute("the max number of authenticated sessions per user")
+    public int getMaxSessionsPerUser()
+    {
+        return _maxSessionsPerUser;
+    }
+
+    /**
+     * @param maxSessionsPerUser the max number of authenticated sessions per user, or a non-positive value for no limit
+     */
+    public void setMaxSessionsPerUser(int maxSessionsPerUser)
+    {
+        _maxSessionsPerUser = maxSessionsPerUser;
+    }
+
+    /**
+     * @return what to do when a user exceeds the max number of sessions
+     */
+    @ManagedAttribute("what to do when a user exceeds the max number of sessions")
+    public ConcurrentSessionPolicy getConcurrentSessionPolicy()
+    {
+        return _concurrentSessionPolicy;
+    }
+
+    /**
+     * @param policy what to do when a user exceeds the max number of sessions
+     */
+    public void setConcurrentSessionPolicy(ConcurrentSessionPolicy policy)
+    {
+        _concurrentSessionPolicy = Objects.requireNonNull(policy);
+    }
+
+    /**
+     * @return the extractor of the key that identifies the user of a session
+     */
+    public UserKeyExtractor getUserKeyExtractor()
+    {
+        return _userKeyExtractor;
+    }
+
+    /**
+     * Set the extractor of the key that identifies the user of a session.
+     * By default, the key is the name of the authenticated principal.
+     *
+     * @param extractor the extractor of the user key
+     */
+    public void setUserKeyExtractor(UserKeyExtractor extractor)
+    {
+        _userKeyExtractor = Objects.requireNonNull(extractor);
+    }
+
     /**
      * Record length of time session has been active. Called when the
      * session is about to be invalidated.
@@ -1188,6 +1655,8 @@ public class SessionHandler extends ScopedHandler
                     if (LOG.isDebugEnabled())
                         LOG.debug("Session {} already invalid", session, e);
                 }
+                _changedAttributes.remove(id);
+                publishSessionEvent(SessionEvent.Type.INVALIDATED, id, null);
             }
         }
         catch (Exception e)
@@ -1330,6 +1799,37 @@ public class SessionHandler extends ScopedHandler
         public Session getSession();
     }
 
+    /**
+     * What to do when a user authenticates a new session but
+     * already has the {@link #getMaxSessionsPerUser() max number of sessions}.
+     */
+    public enum ConcurrentSessionPolicy
+    {
+        /**
+         * The authentication of the new session is denied.
+         */
+        DENY_NEW,
+        /**
+         * The oldest sessions of the user are invalidated.
+         */
+        INVALIDATE_OLDEST
+    }
+
+    /**
+     * Extracts the key that identifies the user of an authenticated session,
+     * for example the principal name, or the principal name qualified by a tenant.
+     */
+    @FunctionalInterface
+    public interface UserKeyExtractor
+    {
+        /**
+         * @param request the authenticated request
+         * @param principal the authenticated user
+         * @return the key of the user, or null if the sessions of the user are not limited
+         */
+        String getUserKey(HttpServletRequest request, Principal principal);
+    }
+
     public static String getSessionCookieName(SessionCookieConfig config)
     {
         if (config == null || config.getName() == null)
@@ -1452,6 +1952,9 @@ public class SessionHandler extends ScopedHandler
 
     public void doSessionAttributeListeners(Session session, String name, Object old, Object value)
     {
+        if (_sessionEventBroadcaster != null)
+            _changedAttributes.computeIfAbsent(session.getId(), k -> ConcurrentHashMap.newKeySet()).add(name);
+
         if (!_sessionAttributeListeners.isEmpty())
         {
             HttpSessionBindingEvent event = new HttpSessionBindingEvent(session, name, old == null ? value : old);
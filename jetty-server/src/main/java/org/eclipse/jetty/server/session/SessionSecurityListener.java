@@ -0,0 +1,76 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.util.EventListener;
+import javax.servlet.http.HttpServletRequest;
+
+/**
+ * SessionSecurityListener
+ *
+ * Audit listener of the security actions taken by a {@link SessionHandler}
+ * on its sessions: the renewal of session ids on authentication, to protect
+ * against session fixation, and the enforcement of the
+ * {@link SessionHandler#getMaxSessionsPerUser() max sessions per user}.
+ *
+ * Listeners are added via {@link SessionHandler#addEventListener(EventListener)}.
+ */
+public interface SessionSecurityListener extends EventListener
+{
+    /**
+     * Invoked when the id of a session is renewed because the request was authenticated.
+     *
+     * @param request the authenticated request
+     * @param session the session, with its new id
+     * @param oldId the id the session had before authentication
+     */
+    default void onSessionIdRenewed(HttpServletRequest request, Session session, String oldId)
+    {
+    }
+
+    /**
+     * Invoked when a session is registered as an authenticated session of a user.
+     *
+     * @param request the authenticated request
+     * @param session the registered session
+     * @param userKey the key of the user
+     */
+    default void onSessionRegistered(HttpServletRequest request, Session session, String userKey)
+    {
+    }
+
+    /**
+     * Invoked when the authentication of a session is denied because
+     * the user already has the max number of sessions.
+     *
+     * @param request the request being authenticated
+     * @param session the denied session
+     * @param userKey the key of the user
+     */
+    default void onSessionDenied(HttpServletRequest request, Session session, String userKey)
+    {
+    }
+
+    /**
+     * Invoked when a session is invalidated to make room for
+     * a new session of the same user.
+     *
+     * @param request the request authenticating the new session
+     * @param id the id of the invalidated session
+     * @param userKey the key of the user
+     */
+    default void onSessionEvicted(HttpServletRequest request, String id, String userKey)
+    {
+    }
+}
@@ -0,0 +1,126 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.TimeUnit;
+import java.util.stream.Collectors;
+import java.util.stream.IntStream;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+@ExtendWith(WorkDirExtension.class)
+public class AsyncRequestLogWriterTest
+{
+    public WorkDir workDir;
+    private Path logFile;
+
+    @BeforeEach
+    public void prepare()
+    {
+        logFile = workDir.getEmptyPathDir().resolve("request.log");
+    }
+
+    @Test
+    public void testDropNewest() throws Exception
+    {
+        AsyncRequestLogWriter writer = new AsyncRequestLogWriter(logFile.toString(), 2);
+        writer.setOverflowPolicy(AsyncRequestLogWriter.OverflowPolicy.DROP_NEWEST);
+
+        // Not started yet, so the entries stay in the queue.
+        writer.write("a");
+        writer.write("b");
+        writer.write("c");
+        assertThat(writer.getDroppedEntries(), is(1L));
+
+        writer.start();
+        writer.stop();
+
+        assertThat(Files.readAllLines(logFile), is(List.of("a", "b")));
+    }
+
+    @Test
+    public void testDropOldest() throws Exception
+    {
+        AsyncRequestLogWriter writer = new AsyncRequestLogWriter(logFile.toString(), 2);
+        writer.setOverflowPolicy(AsyncRequestLogWriter.OverflowPolicy.DROP_OLDEST);
+
+        writer.write("a");
+        writer.write("b");
+        writer.write("c");
+        assertThat(writer.getDroppedEntries(), is(1L));
+
+        writer.start();
+        writer.stop();
+
+        assertThat(Files.readAllLines(logFile), is(List.of("b", "c")));
+    }
+
+    @Test
+    public void testBlock() throws Exception
+    {
+        AsyncRequestLogWriter writer = new AsyncRequestLogWriter(logFile.toString(), 1);
+        writer.setOverflowPolicy(AsyncRequestLogWriter.OverflowPolicy.BLOCK);
+
+        writer.write("a");
+        CompletableFuture<Void> blocked = CompletableFuture.runAsync(() ->
+        {
+            try
+            {
+                writer.write("b");
+            }
+            catch (Exception x)
+            {
+                throw new RuntimeException(x);
+            }
+        });
+        Thread.sleep(500);
+        assertThat(blocked.isDone(), is(false));
+
+        writer.start();
+        blocked.get(5, TimeUnit.SECONDS);
+        writer.stop();
+
+        assertThat(writer.getDroppedEntries(), is(0L));
+        assertThat(Files.readAllLines(logFile), is(List.of("a", "b")));
+    }
+
+    @Test
+    public void testStopDrainsQueue() throws Exception
+    {
+        AsyncRequestLogWriter writer = new AsyncRequestLogWriter(logFile.toString(), 1024);
+        writer.setBatchSize(8);
+        writer.start();
+
+        List<String> entries = IntStream.range(0, 500).mapToObj(i -> "entry-" + i).collect(Collectors.toList());
+        for (String entry : entries)
+        {
+            writer.write(entry);
+        }
+        writer.stop();
+
+        assertThat(writer.getQueueSize(), is(0));
+        assertThat(Files.readAllLines(logFile), is(entries));
+    }
+}
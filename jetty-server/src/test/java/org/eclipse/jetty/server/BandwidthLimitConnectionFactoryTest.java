@@ -0,0 +1,94 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.nio.channels.SocketChannel;
+import java.util.Arrays;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.NanoTime;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class BandwidthLimitConnectionFactoryTest
+{
+    private static final int CONTENT_LENGTH = 8192;
+
+    private Server _server;
+    private ServerConnector _connector;
+
+    private void start(BandwidthLimitConnectionFactory bandwidth) throws Exception
+    {
+        _server = new Server();
+        HttpConnectionFactory http = new HttpConnectionFactory();
+        _connector = new ServerConnector(_server, 1, 1, bandwidth, http);
+        _server.addConnector(_connector);
+        _server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                byte[] content = new byte[CONTENT_LENGTH];
+                Arrays.fill(content, (byte)'x');
+                response.setContentLength(content.length);
+                response.getOutputStream().write(content);
+            }
+        });
+        _server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (_server != null)
+            _server.stop();
+    }
+
+    @Test
+    public void testWriteRateLimitsConnection() throws Exception
+    {
+        BandwidthLimitConnectionFactory bandwidth = new BandwidthLimitConnectionFactory();
+        // 2048 bytes in the burst, then at least 6144 bytes at 8192 bytes/s.
+        bandwidth.setWriteRate(CONTENT_LENGTH);
+        bandwidth.setBurst(2048);
+        start(bandwidth);
+
+        try (SocketChannel client = SocketChannel.open(new InetSocketAddress("localhost", _connector.getLocalPort())))
+        {
+            HttpTester.Request request = HttpTester.newRequest();
+            request.put(HttpHeader.HOST, "localhost");
+            long begin = NanoTime.now();
+            client.write(request.generate());
+            HttpTester.Response response = HttpTester.parseResponse(HttpTester.from(client));
+
+            assertThat(response, notNullValue());
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.getContentBytes().length, is(CONTENT_LENGTH));
+            assertThat(NanoTime.millisSince(begin), greaterThanOrEqualTo(500L));
+        }
+    }
+}
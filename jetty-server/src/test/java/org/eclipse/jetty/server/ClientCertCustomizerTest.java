@@ -0,0 +1,197 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.FileInputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.nio.charset.StandardCharsets;
+import java.security.KeyStore;
+import java.security.cert.X509Certificate;
+import java.util.Base64;
+import javax.net.ssl.SSLSocket;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.http.StructuredFields;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.nullValue;
+
+public class ClientCertCustomizerTest
+{
+    private static final String KEYSTORE = "src/test/resources/keystore.p12";
+
+    private Server server;
+    private X509Certificate certificate;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        try (InputStream input = new FileInputStream(KEYSTORE))
+        {
+            keyStore.load(input, "storepwd".toCharArray());
+        }
+        certificate = (X509Certificate)keyStore.getCertificate(keyStore.aliases().nextElement());
+        server = new Server();
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                X509Certificate[] certs = (X509Certificate[])request.getAttribute(SecureRequestCustomizer.JAVAX_SERVLET_REQUEST_X_509_CERTIFICATE);
+                if (certs != null)
+                    response.setHeader("X-Subject", certs[0].getSubjectX500Principal().getName());
+                String clientCert = request.getHeader(ClientCertHeadersCustomizer.CLIENT_CERT);
+                if (clientCert != null)
+                    response.setHeader("X-Client-Cert", clientCert);
+            }
+        });
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private LocalConnector startBackend(ForwardedClientCertCustomizer customizer) throws Exception
+    {
+        HttpConfiguration httpConfig = new HttpConfiguration();
+        httpConfig.addCustomizer(customizer);
+        LocalConnector connector = new LocalConnector(server, new HttpConnectionFactory(httpConfig));
+        server.addConnector(connector);
+        server.start();
+        return connector;
+    }
+
+    private String clientCertHeader() throws Exception
+    {
+        return ":" + Base64.getEncoder().encodeToString(certificate.getEncoded()) + ":";
+    }
+
+    @Test
+    public void testForwardedFromTrustedProxy() throws Exception
+    {
+        ForwardedClientCertCustomizer customizer = new ForwardedClientCertCustomizer();
+        // The remote address of LocalConnector endpoints is 0.0.0.0.
+        customizer.addTrustedProxy("0.0.0.0");
+        LocalConnector connector = startBackend(customizer);
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(
+            "GET / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Client-Cert: " + clientCertHeader() + "\r\n" +
+                "Client-Cert-Chain: " + clientCertHeader() + "\r\n" +
+                "Connection: close\r\n" +
+                "\r\n"));
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Subject"), is(certificate.getSubjectX500Principal().getName()));
+    }
+
+    @Test
+    public void testUntrustedProxyIgnored() throws Exception
+    {
+        ForwardedClientCertCustomizer customizer = new ForwardedClientCertCustomizer();
+        customizer.addTrustedProxy("10.0.0.1");
+        LocalConnector connector = startBackend(customizer);
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(
+            "GET / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Client-Cert: " + clientCertHeader() + "\r\n" +
+                "Connection: close\r\n" +
+                "\r\n"));
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Subject"), nullValue());
+        // The header is removed before reaching the application.
+        assertThat(response.get("X-Client-Cert"), nullValue());
+    }
+
+    @Test
+    public void testInvalidHeaderFromTrustedProxy() throws Exception
+    {
+        ForwardedClientCertCustomizer customizer = new ForwardedClientCertCustomizer();
+        customizer.addTrustedProxy("0.0.0.0");
+        LocalConnector connector = startBackend(customizer);
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(
+            "GET / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Client-Cert: :AAAA:\r\n" +
+                "Connection: close\r\n" +
+                "\r\n"));
+
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+    }
+
+    @Test
+    public void testClientCertHeadersFromTlsSession() throws Exception
+    {
+        SslContextFactory.Server serverTLS = new SslContextFactory.Server();
+        serverTLS.setKeyStorePath(KEYSTORE);
+        serverTLS.setKeyStorePassword("storepwd");
+        serverTLS.setTrustStorePath(KEYSTORE);
+        serverTLS.setTrustStorePassword("storepwd");
+        serverTLS.setNeedClientAuth(true);
+
+        HttpConfiguration httpConfig = new HttpConfiguration();
+        httpConfig.addCustomizer(new SecureRequestCustomizer());
+        httpConfig.addCustomizer(new ClientCertHeadersCustomizer());
+        ServerConnector connector = new ServerConnector(server, serverTLS, new HttpConnectionFactory(httpConfig));
+        server.addConnector(connector);
+        server.start();
+
+        SslContextFactory.Client clientTLS = new SslContextFactory.Client(true);
+        clientTLS.setKeyStorePath(KEYSTORE);
+        clientTLS.setKeyStorePassword("storepwd");
+        clientTLS.start();
+        try (SSLSocket socket = (SSLSocket)clientTLS.getSslContext().getSocketFactory().createSocket("localhost", connector.getLocalPort()))
+        {
+            OutputStream output = socket.getOutputStream();
+            output.write((
+                "GET / HTTP/1.1\r\n" +
+                    "Host: localhost\r\n" +
+                    "Client-Cert: :AAAA:\r\n" +
+                    "Connection: close\r\n" +
+                    "\r\n").getBytes(StandardCharsets.UTF_8));
+            output.flush();
+
+            HttpTester.Response response = HttpTester.parseResponse(HttpTester.from(socket.getInputStream()));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            String clientCert = response.get("X-Client-Cert");
+            // The header sent by the client is replaced with the one from the TLS session.
+            assertThat(clientCert, not(is(":AAAA:")));
+            assertThat(StructuredFields.parseItem(clientCert).asBytes(), is(certificate.getEncoded()));
+        }
+        finally
+        {
+            clientTLS.stop();
+        }
+    }
+}
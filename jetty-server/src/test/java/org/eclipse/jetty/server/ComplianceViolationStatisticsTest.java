@@ -0,0 +1,111 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpCompliance;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class ComplianceViolationStatisticsTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private ComplianceViolationStatistics _statistics;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().setHttpCompliance(HttpCompliance.PARANOID);
+        _statistics = new ComplianceViolationStatistics();
+        _connector.addBean(_statistics);
+        _server.addConnector(_connector);
+        _server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                // Read the content so that the chunks are parsed.
+                request.getInputStream().readAllBytes();
+            }
+        });
+        _server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    @Test
+    public void testRejectedSmugglingAttemptsAreCounted() throws Exception
+    {
+        String request = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Transfer-Encoding: chunked\r\n" +
+            "Content-Length: 5\r\n" +
+            "\r\n" +
+            "0\r\n" +
+            "\r\n";
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(request));
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+
+        request = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Transfer-Encoding: chunked\r\n" +
+            "\r\n" +
+            "1;x=\"\r\n" +
+            "X\r\n" +
+            "0\r\n" +
+            "\r\n";
+        response = HttpTester.parseResponse(_connector.getResponse(request));
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+
+        assertThat(_statistics.getRejectedViolations(), is(2L));
+        assertThat(_statistics.getRejectedViolationsByName().get(HttpCompliance.Violation.TRANSFER_ENCODING_WITH_CONTENT_LENGTH.getName()), is(1L));
+        assertThat(_statistics.getRejectedViolationsByName().get(HttpCompliance.Violation.INVALID_CHUNK_EXTENSION.getName()), is(1L));
+        assertThat(_statistics.getAllowedViolations(), is(0L));
+    }
+
+    @Test
+    public void testValidRequestIsNotCounted() throws Exception
+    {
+        String request = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Transfer-Encoding: chunked\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            "1;name=value\r\n" +
+            "X\r\n" +
+            "0\r\n" +
+            "\r\n";
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(request));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(_statistics.getRejectedViolations(), is(0L));
+    }
+}
@@ -0,0 +1,196 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.net.Socket;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.empty;
+import static org.hamcrest.Matchers.is;
+
+public class ConnectionAdmissionControlTest
+{
+    private Server server;
+    private ServerConnector connector;
+    private ConnectionAdmissionControl admission;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new ServerConnector(server, 1, 1);
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+            }
+        });
+        admission = new ConnectionAdmissionControl(server);
+        server.addBean(admission);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private Socket connect() throws IOException
+    {
+        Socket socket = new Socket("127.0.0.1", connector.getLocalPort());
+        socket.setSoTimeout(5000);
+        return socket;
+    }
+
+    private static boolean isAdmitted(Socket socket) throws IOException
+    {
+        socket.getOutputStream().write("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n".getBytes(StandardCharsets.UTF_8));
+        InputStream input = socket.getInputStream();
+        byte[] buffer = new byte[1024];
+        try
+        {
+            int read = input.read(buffer);
+            if (read < 0)
+                return false;
+            assertThat(new String(buffer, 0, read, StandardCharsets.UTF_8), containsString(" 200 "));
+            return true;
+        }
+        catch (IOException x)
+        {
+            // Connection reset by the server.
+            return false;
+        }
+    }
+
+    @Test
+    public void testMaxConnectionsPerAddress() throws Exception
+    {
+        admission.setMaxConnectionsPerAddress(2);
+        server.start();
+
+        try (Socket socket1 = connect();
+             Socket socket2 = connect())
+        {
+            assertThat(isAdmitted(socket1), is(true));
+            assertThat(isAdmitted(socket2), is(true));
+
+            try (Socket socket3 = connect())
+            {
+                assertThat(isAdmitted(socket3), is(false));
+            }
+            assertThat(admission.getRejectedByAddress(), is(1L));
+            assertThat(admission.getConnections("127.0.0.1"), is(2));
+        }
+
+        // Closing the connections releases the address slots.
+        await().atMost(5, TimeUnit.SECONDS).until(admission::getConnections, is(0));
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(true));
+        }
+    }
+
+    @Test
+    public void testMaxConnectionsPerSubnet() throws Exception
+    {
+        admission.setMaxConnectionsPerSubnet(1);
+        admission.setIPv4SubnetPrefixLength(8);
+        server.start();
+
+        try (Socket socket1 = connect())
+        {
+            assertThat(isAdmitted(socket1), is(true));
+            try (Socket socket2 = connect())
+            {
+                assertThat(isAdmitted(socket2), is(false));
+            }
+        }
+        assertThat(admission.getRejectedBySubnet(), is(1L));
+        assertThat(admission.getRejectedByAddress(), is(0L));
+    }
+
+    @Test
+    public void testAcceptRateGreylists() throws Exception
+    {
+        admission.setMaxAcceptRatePerAddress(3);
+        admission.setAcceptRatePeriod(TimeUnit.MINUTES.toMillis(1));
+        admission.setGreylistPeriod(TimeUnit.MINUTES.toMillis(1));
+        server.start();
+
+        for (int i = 0; i < 3; ++i)
+        {
+            try (Socket socket = connect())
+            {
+                assertThat(isAdmitted(socket), is(true));
+            }
+        }
+
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(false));
+        }
+        assertThat(admission.getGreylist(), contains("127.0.0.1"));
+        assertThat(admission.getGreylistedCount(), is(1L));
+
+        // Greylisted addresses are rejected even if below the rate.
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(false));
+        }
+        assertThat(admission.getRejectedByGreylist(), is(2L));
+
+        assertThat(admission.ungreylist("127.0.0.1"), is(true));
+        assertThat(admission.getGreylist(), empty());
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(true));
+        }
+    }
+
+    @Test
+    public void testGreylistExpires() throws Exception
+    {
+        admission.setGreylistPeriod(500);
+        server.start();
+
+        admission.greylist("127.0.0.1");
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(false));
+        }
+
+        await().atMost(5, TimeUnit.SECONDS).until(admission::getGreylist, empty());
+        try (Socket socket = connect())
+        {
+            assertThat(isAdmitted(socket), is(true));
+        }
+    }
+}
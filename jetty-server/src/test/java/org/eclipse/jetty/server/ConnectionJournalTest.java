@@ -0,0 +1,144 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.net.Socket;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.io.ConnectionJournal;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.nullValue;
+import static org.hamcrest.Matchers.startsWith;
+
+public class ConnectionJournalTest
+{
+    private Server server;
+    private ServerConnector connector;
+    private ConnectionJournal journal;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server, 1, 1);
+        connector.setIdleTimeout(500);
+        journal = new ConnectionJournal();
+        connector.addBean(journal);
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+            }
+        });
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private static String event(String entry)
+    {
+        // Strip the timestamp.
+        return entry.substring(entry.indexOf(' ') + 1);
+    }
+
+    @Test
+    public void testIdleTimeoutIsRecorded() throws Exception
+    {
+        try (Socket socket = new Socket("localhost", connector.getLocalPort()))
+        {
+            OutputStream output = socket.getOutputStream();
+            output.write("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+
+            await().atMost(5, TimeUnit.SECONDS).until(() -> journal.getOpenConnectionIds().size() == 1);
+            long id = journal.getOpenConnectionIds().get(0);
+
+            // Wait for the idle timeout to close the connection.
+            InputStream input = socket.getInputStream();
+            socket.setSoTimeout(5000);
+            while (true)
+            {
+                if (input.read() < 0)
+                    break;
+            }
+
+            await().atMost(5, TimeUnit.SECONDS).until(() -> journal.getClosedConnectionIds().contains(id));
+            List<String> events = journal.getEvents(id);
+            assertThat(event(events.get(0)), startsWith("OPENED "));
+            assertThat(event(events.get(1)), is("CONNECTION HttpConnection"));
+            assertThat(events.stream().anyMatch(e -> event(e).startsWith("IDLE_TIMEOUT HttpConnection idleTimeout=500ms")), is(true));
+            assertThat(events.stream().anyMatch(e -> event(e).startsWith("CLOSED HttpConnection")), is(true));
+
+            String text = journal.getConnectionJournal(id);
+            assertThat(text, startsWith("connection#" + id));
+            assertThat(text, containsString("IDLE_TIMEOUT"));
+            assertThat(journal.dump(), containsString("connection#" + id));
+        }
+    }
+
+    @Test
+    public void testBoundedJournals() throws Exception
+    {
+        journal.setMaxEvents(2);
+        journal.setMaxClosedConnections(1);
+
+        long lastId = -1;
+        for (int i = 0; i < 3; ++i)
+        {
+            try (Socket socket = new Socket("localhost", connector.getLocalPort()))
+            {
+                OutputStream output = socket.getOutputStream();
+                output.write("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n".getBytes(StandardCharsets.UTF_8));
+                output.flush();
+                InputStream input = socket.getInputStream();
+                while (true)
+                {
+                    if (input.read() < 0)
+                        break;
+                }
+            }
+            await().atMost(5, TimeUnit.SECONDS).until(() -> journal.getOpenConnectionIds().isEmpty());
+            lastId = journal.getClosedConnectionIds().get(0);
+        }
+
+        assertThat(journal.getClosedConnectionIds(), hasSize(1));
+        assertThat(journal.getClosedConnectionIds().get(0), is(lastId));
+        assertThat(journal.getEvents(lastId), hasSize(2));
+        assertThat(journal.getConnectionJournal(lastId), not(containsString("dropped=0")));
+        assertThat(journal.getEvents(lastId - 2), nullValue());
+    }
+}
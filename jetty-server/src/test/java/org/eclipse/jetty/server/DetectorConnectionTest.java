@@ -318,6 +318,53 @@ public class DetectorConnectionTest
         assertThat(response, Matchers.containsString("HTTP/1.1 200"));
     }
 
+    @Test
+    public void testDetectionTimeoutFallsBackToFallbackProtocol() throws Exception
+    {
+        ConnectionFactory.Detecting detectingAlwaysNeedMoreBytes = new ConnectionFactory.Detecting()
+        {
+            @Override
+            public Detection detect(ByteBuffer buffer)
+            {
+                return Detection.NEED_MORE_BYTES;
+            }
+
+            @Override
+            public String getProtocol()
+            {
+                return "neverenough";
+            }
+
+            @Override
+            public List<String> getProtocols()
+            {
+                return List.of(getProtocol());
+            }
+
+            @Override
+            public Connection newConnection(Connector connector, EndPoint endPoint)
+            {
+                throw new AssertionError();
+            }
+        };
+
+        HttpConnectionFactory http = new HttpConnectionFactory();
+        DetectorConnectionFactory detector = new DetectorConnectionFactory(detectingAlwaysNeedMoreBytes);
+        detector.setDetectionTimeout(500);
+        detector.setFallbackProtocol(http.getProtocol());
+
+        start(detector, new ProxyConnectionFactory(http.getProtocol()), http);
+
+        String request = "GET /path HTTP/1.1\n" +
+            "Host: server:80\n" +
+            "Connection: close\n" +
+            "\n";
+        String response = getResponse(request);
+
+        assertThat(response, Matchers.containsString("HTTP/1.1 200"));
+        assertThat(response, Matchers.containsString("pathInfo=/path"));
+    }
+
     @Test
     public void testDetectorToNextDetector() throws Exception
     {
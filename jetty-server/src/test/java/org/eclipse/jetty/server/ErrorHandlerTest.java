@@ -96,6 +96,11 @@ public class ErrorHandlerTest
                     throw new ServletException(new BadMessageException(code));
                 }
 
+                if (target.startsWith("/problem/"))
+                {
+                    throw new ServletException(new IllegalArgumentException("invalid id " + target.substring(target.lastIndexOf('/') + 1)));
+                }
+
                 // produce an exception with an JSON formatted cause message
                 if (target.startsWith("/jsonmessage/"))
                 {
@@ -696,6 +701,141 @@ public class ErrorHandlerTest
         }
     }
 
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> assertProblem(HttpTester.Response response)
+    {
+        assertThat("Response Content-Type", response.get(HttpHeader.CONTENT_TYPE), containsString(ErrorHandler.PROBLEM_JSON));
+        Map<String, Object> problem = (Map<String, Object>)new JSON().fromJSON(response.getContent());
+        assertThat(((Number)problem.get("status")).intValue(), is(response.getStatus()));
+        assertThat(problem.get("title"), notNullValue());
+        return problem;
+    }
+
+    @Test
+    public void testProblemDetailsAccept() throws Exception
+    {
+        // Problem details are generated when explicitly accepted, even if not enabled.
+        String rawResponse = connector.getResponse(
+            "GET /nothing HTTP/1.1\r\n" +
+                "Host: Localhost\r\n" +
+                "Accept: application/problem+json, text/html;q=0.5\r\n" +
+                "\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat("Response status code", response.getStatus(), is(404));
+        Map<String, Object> problem = assertProblem(response);
+        assertThat(problem.get("type"), is("about:blank"));
+        assertThat(problem.get("title"), is("Not Found"));
+        assertThat(problem.get("instance"), is("/nothing"));
+        assertThat(problem.containsKey("detail"), is(false));
+    }
+
+    @Test
+    public void testProblemDetailsMode() throws Exception
+    {
+        server.getErrorHandler().setProblemDetails(true);
+
+        for (String accept : new String[]{null, "*/*", "application/json"})
+        {
+            String rawResponse = connector.getResponse(
+                "GET /badmessage/444 HTTP/1.1\r\n" +
+                    "Host: Localhost\r\n" +
+                    (accept == null ? "" : "Accept: " + accept + "\r\n") +
+                    "\r\n");
+            HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+            assertThat("Response status code", response.getStatus(), is(444));
+            Map<String, Object> problem = assertProblem(response);
+            assertThat(problem.get("instance"), is("/badmessage/444"));
+        }
+
+        // HTML is still served when preferred.
+        String rawResponse = connector.getResponse(
+            "GET /badmessage/444 HTTP/1.1\r\n" +
+                "Host: Localhost\r\n" +
+                "Accept: text/html\r\n" +
+                "\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+        assertThat("Response Content-Type", response.get(HttpHeader.CONTENT_TYPE), containsString("text/html"));
+    }
+
+    @Test
+    public void testProblemDetailsMapper() throws Exception
+    {
+        ErrorHandler errorHandler = server.getErrorHandler();
+        errorHandler.setProblemDetails(true);
+        errorHandler.setShowStacks(false);
+        errorHandler.addProblemMapper((request, code, failure) ->
+        {
+            if (failure instanceof IllegalArgumentException)
+                return new ErrorHandler.ProblemDetails("https://example.com/problems/invalid-id", "Invalid Identifier")
+                    .with("id", request.getRequestURI().substring(request.getRequestURI().lastIndexOf('/') + 1))
+                    .with("retryable", false);
+            return null;
+        });
+
+        String rawResponse = connector.getResponse(
+            "GET /problem/42 HTTP/1.1\r\n" +
+                "Host: Localhost\r\n" +
+                "\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat("Response status code", response.getStatus(), is(500));
+        Map<String, Object> problem = assertProblem(response);
+        assertThat(problem.get("type"), is("https://example.com/problems/invalid-id"));
+        assertThat(problem.get("title"), is("Invalid Identifier"));
+        assertThat(problem.get("id"), is("42"));
+        assertThat(problem.get("retryable"), is(false));
+        assertThat((String)problem.get("detail"), containsString("invalid id 42"));
+        assertThat(problem.containsKey("stackTrace"), is(false));
+
+        // Unmapped exceptions are about:blank problems.
+        rawResponse = connector.getResponse(
+            "GET /htmlmessage/ HTTP/1.1\r\n" +
+                "Host: Localhost\r\n" +
+                "\r\n");
+        response = HttpTester.parseResponse(rawResponse);
+        assertThat(assertProblem(response).get("type"), is("about:blank"));
+    }
+
+    @Test
+    public void testProblemDetailsRedactServerErrors() throws Exception
+    {
+        ErrorHandler errorHandler = server.getErrorHandler();
+        errorHandler.setProblemDetails(true);
+        errorHandler.setShowStacks(true);
+
+        String request = "GET /problem/42 HTTP/1.1\r\n" +
+            "Host: Localhost\r\n" +
+            "\r\n";
+        Map<String, Object> problem = assertProblem(HttpTester.parseResponse(connector.getResponse(request)));
+        assertThat((String)problem.get("detail"), containsString("invalid id 42"));
+        assertThat((String)problem.get("stackTrace"), containsString(IllegalArgumentException.class.getName()));
+
+        errorHandler.setRedactServerErrors(true);
+        problem = assertProblem(HttpTester.parseResponse(connector.getResponse(request)));
+        assertThat(problem.get("title"), is("Server Error"));
+        assertThat(problem.containsKey("detail"), is(false));
+        assertThat(problem.containsKey("stackTrace"), is(false));
+        assertThat(problem.containsKey("servlet"), is(false));
+    }
+
+    @Test
+    public void testProblemDetailsBadMessage() throws Exception
+    {
+        server.getErrorHandler().setProblemDetails(true);
+
+        String rawResponse = connector.getResponse(
+            "GET / HTTP/1.1\r\n" +
+                "Host: Localhost\r\n" +
+                "Content-Length: invalid\r\n" +
+                "\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat("Response status code", response.getStatus(), is(400));
+        assertProblem(response);
+    }
+
     @Test
     public void testErrorContextRecycle() throws Exception
     {
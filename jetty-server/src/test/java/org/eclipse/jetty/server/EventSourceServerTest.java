@@ -0,0 +1,158 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.BufferedReader;
+import java.io.IOException;
+import java.io.InputStreamReader;
+import java.net.Socket;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class EventSourceServerTest
+{
+    private final BlockingQueue<EventSourceServer.Client> _opened = new LinkedBlockingQueue<>();
+    private final CountDownLatch _closed = new CountDownLatch(1);
+    private Server _server;
+    private ServerConnector _connector;
+    private EventSourceServer _eventSourceServer;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _server = new Server();
+        _connector = new ServerConnector(_server);
+        _server.addConnector(_connector);
+        _eventSourceServer = new EventSourceServer();
+        _eventSourceServer.setHeartBeatPeriod(0);
+        _server.addBean(_eventSourceServer);
+        _server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                if (!EventSourceServer.isEventStream(request))
+                    return;
+                _eventSourceServer.accept(baseRequest, response, new EventSourceServer.Listener()
+                {
+                    @Override
+                    public void onOpen(EventSourceServer.Client client)
+                    {
+                        client.send(new EventSourceServer.Event("replay after " + client.getLastEventId()).name("replay"));
+                        _opened.offer(client);
+                    }
+
+                    @Override
+                    public void onClose(EventSourceServer.Client client)
+                    {
+                        _closed.countDown();
+                    }
+                });
+            }
+        });
+        _server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private BufferedReader connect(Socket socket, String lastEventId) throws Exception
+    {
+        String request = "GET / HTTP/1.0\r\n" +
+            "Accept: text/event-stream\r\n" +
+            (lastEventId == null ? "" : "Last-Event-ID: " + lastEventId + "\r\n") +
+            "\r\n";
+        socket.getOutputStream().write(request.getBytes(StandardCharsets.UTF_8));
+        socket.getOutputStream().flush();
+        BufferedReader reader = new BufferedReader(new InputStreamReader(socket.getInputStream(), StandardCharsets.UTF_8));
+        String line = reader.readLine();
+        assertThat(line, containsString(" 200 "));
+        boolean eventStream = false;
+        while (!(line = reader.readLine()).isEmpty())
+        {
+            if (line.toLowerCase().startsWith("content-type:") && line.contains(EventSourceServer.TEXT_EVENT_STREAM))
+                eventStream = true;
+        }
+        assertTrue(eventStream);
+        return reader;
+    }
+
+    @Test
+    public void testEventsAndLastEventId() throws Exception
+    {
+        try (Socket socket = new Socket("localhost", _connector.getLocalPort()))
+        {
+            socket.setSoTimeout(5000);
+            BufferedReader reader = connect(socket, "41");
+            EventSourceServer.Client client = _opened.poll(5, TimeUnit.SECONDS);
+            assertEquals("41", client.getLastEventId());
+
+            assertEquals("event: replay", reader.readLine());
+            assertEquals("data: replay after 41", reader.readLine());
+            assertEquals("", reader.readLine());
+
+            _eventSourceServer.broadcast(new EventSourceServer.Event("line1\nline2").id("42"));
+            assertEquals("id: 42", reader.readLine());
+            assertEquals("data: line1", reader.readLine());
+            assertEquals("data: line2", reader.readLine());
+            assertEquals("", reader.readLine());
+            assertEquals("42", client.getLastEventId());
+
+            client.comment("ping");
+            assertEquals(": ping", reader.readLine());
+            assertEquals("", reader.readLine());
+
+            client.close();
+            assertTrue(_closed.await(5, TimeUnit.SECONDS));
+            assertThat(reader.readLine(), nullValue());
+            assertThat(client.send("after close"), is(false));
+            assertThat(_eventSourceServer.getClientCount(), is(0));
+        }
+    }
+
+    @Test
+    public void testInvalidConfiguration()
+    {
+        assertThrows(IllegalArgumentException.class, () -> _eventSourceServer.setMaxQueueSize(0));
+        assertThrows(IllegalArgumentException.class, () -> new EventSourceServer.Event().name("a\nb"));
+    }
+
+    @Test
+    public void testEventFormat()
+    {
+        byte[] bytes = new EventSourceServer.Event("a\r\nb").id("1").name("update").retry(1000).toBytes();
+        assertEquals("id: 1\nevent: update\nretry: 1000\ndata: a\ndata: b\n\n", new String(bytes, StandardCharsets.UTF_8));
+    }
+}
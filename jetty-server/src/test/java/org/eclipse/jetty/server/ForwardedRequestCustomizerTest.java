@@ -14,6 +14,7 @@
 package org.eclipse.jetty.server;
 
 import java.io.IOException;
+import java.util.List;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicReference;
 import java.util.function.Consumer;
@@ -28,6 +29,7 @@ import org.eclipse.jetty.http.HttpTester;
 import org.eclipse.jetty.server.handler.AbstractHandler;
 import org.junit.jupiter.api.AfterEach;
 import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
 import org.junit.jupiter.params.ParameterizedTest;
 import org.junit.jupiter.params.provider.Arguments;
 import org.junit.jupiter.params.provider.MethodSource;
@@ -57,6 +59,8 @@ public class ForwardedRequestCustomizerTest
         final AtomicReference<Integer> remotePort = new AtomicReference<>();
         final AtomicReference<String> sslSession = new AtomicReference<>();
         final AtomicReference<String> sslCertificate = new AtomicReference<>();
+        final AtomicReference<Object> clientAddress = new AtomicReference<>();
+        final AtomicReference<Object> chain = new AtomicReference<>();
     }
 
     private Actual actual;
@@ -118,6 +122,8 @@ public class ForwardedRequestCustomizerTest
             actual.remoteAddr.set(request.getRemoteAddr());
             actual.remotePort.set(request.getRemotePort());
             actual.requestURL.set(request.getRequestURL().toString());
+            actual.clientAddress.set(request.getAttribute(ForwardedRequestCustomizer.CLIENT_ADDRESS_ATTRIBUTE));
+            actual.chain.set(request.getAttribute(ForwardedRequestCustomizer.FORWARDED_CHAIN_ATTRIBUTE));
             return true;
         };
 
@@ -1116,6 +1122,111 @@ public class ForwardedRequestCustomizerTest
         assertThat("status", response.getStatus(), is(400));
     }
 
+    private void send(String... headers) throws Exception
+    {
+        String rawRequest = new Request("raw").headers(headers).getRawRequest(header -> header);
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(rawRequest));
+        assertThat("status", response.getStatus(), is(200));
+    }
+
+    @SuppressWarnings("unchecked")
+    private List<ForwardedRequestCustomizer.Hop> chain()
+    {
+        return (List<ForwardedRequestCustomizer.Hop>)actual.chain.get();
+    }
+
+    @Test
+    public void testChainWithoutTrustedProxies() throws Exception
+    {
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "Forwarded: for=1.1.1.1;host=evil.com, for=192.0.2.43;proto=https;host=example.com",
+            "Forwarded: for=10.1.1.1;x-ext=\"a b\"");
+
+        // Every hop is trusted and the left-most values win.
+        assertThat(actual.remoteAddr.get(), is("1.1.1.1"));
+        assertThat(actual.serverName.get(), is("evil.com"));
+        assertThat(actual.scheme.get(), is("https"));
+        assertThat(actual.clientAddress.get(), is("1.1.1.1"));
+        List<ForwardedRequestCustomizer.Hop> chain = chain();
+        assertThat(chain.size(), is(3));
+        assertThat(chain.get(1).getFor(), is("192.0.2.43"));
+        assertThat(chain.get(1).getProto(), is("https"));
+        assertThat(chain.get(2).getParameter("x-ext"), is("a b"));
+        assertThat(chain.get(2).toString(), is("for=10.1.1.1;x-ext=\"a b\""));
+        assertTrue(chain.stream().allMatch(ForwardedRequestCustomizer.Hop::isVerified));
+    }
+
+    @Test
+    public void testChainVerifiedByTrustedProxies() throws Exception
+    {
+        // The test connector peer address is 0.0.0.0.
+        customizer.setTrustedProxies("0.0.0.0", "10.0.0.0/8");
+
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "Forwarded: for=1.1.1.1;host=evil.com, for=192.0.2.43;proto=https;host=example.com, for=10.1.1.1");
+
+        // The first hop has been added by the client itself.
+        assertThat(actual.remoteAddr.get(), is("192.0.2.43"));
+        assertThat(actual.serverName.get(), is("example.com"));
+        assertThat(actual.scheme.get(), is("https"));
+        assertThat(actual.clientAddress.get(), is("192.0.2.43"));
+        List<ForwardedRequestCustomizer.Hop> chain = chain();
+        assertThat(chain.size(), is(3));
+        assertThat(chain.get(0).isVerified(), is(false));
+        assertThat(chain.get(1).isVerified(), is(true));
+        assertThat(chain.get(2).isVerified(), is(true));
+
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "X-Forwarded-For: 6.6.6.6, 192.0.2.43",
+            "X-Forwarded-For: 10.0.0.1");
+        assertThat(actual.remoteAddr.get(), is("192.0.2.43"));
+        assertThat(actual.clientAddress.get(), is("192.0.2.43"));
+    }
+
+    @Test
+    public void testUntrustedPeer() throws Exception
+    {
+        customizer.setTrustedProxies("10.0.0.0/8");
+
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "Forwarded: for=192.0.2.43;host=example.com",
+            "X-Forwarded-For: 1.2.3.4");
+
+        assertThat(actual.remoteAddr.get(), is("0.0.0.0"));
+        assertThat(actual.serverName.get(), is("myhost"));
+        assertThat(actual.clientAddress.get() == null, is(true));
+        List<ForwardedRequestCustomizer.Hop> chain = chain();
+        assertThat(chain.size(), is(1));
+        assertThat(chain.get(0).isVerified(), is(false));
+    }
+
+    @Test
+    public void testObfuscatedIdentifiers() throws Exception
+    {
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "Forwarded: for=_hidden;by=_proxy, for=\"192.0.2.43:_port\"");
+
+        assertThat(actual.remoteAddr.get(), is("192.0.2.43"));
+        assertThat(actual.remotePort.get(), is(0));
+        assertThat(actual.clientAddress.get(), is("192.0.2.43:_port"));
+        assertThat(chain().get(0).getFor(), is("_hidden"));
+        assertThat(chain().get(0).getBy(), is("_proxy"));
+
+        customizer.setTrustedProxies("0.0.0.0");
+        send("GET / HTTP/1.1",
+            "Host: myhost",
+            "Forwarded: for=192.0.2.43, for=_hidden");
+
+        // An obfuscated client is reported, but is not a remote address.
+        assertThat(actual.remoteAddr.get(), is("0.0.0.0"));
+        assertThat(actual.clientAddress.get(), is("_hidden"));
+    }
+
     public static Stream<Arguments> customHeaderNameRequestCases()
     {
         return Stream.of(
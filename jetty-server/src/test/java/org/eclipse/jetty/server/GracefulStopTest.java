@@ -333,6 +333,26 @@ public class GracefulStopTest
         assertHandled(handler, true);
     }
 
+    @Test
+    public void testConnectorDrain() throws Exception
+    {
+        Socket client0 = newClientBusy(POST_12345, handler);
+        Socket client1 = newClientIdle(POST_12345, handler);
+
+        backgroundComplete(client0, handler);
+
+        connector.setDrainWaitTime(5000);
+        connector.drain().get(5, TimeUnit.SECONDS);
+
+        assertThat(connector.getDrainPhase(), is(AbstractConnector.DrainPhase.DRAINED));
+        assertResponse(client0, true);
+        assertQuickClose(client0);
+        assertQuickClose(client1);
+        assertThat(connector.getConnectedEndPointCount(), is(0));
+        assertThat(connector.isAccepting(), is(false));
+        assertHandled(handler, false);
+    }
+
     @Test
     public void testGracefulWithContext() throws Exception
     {
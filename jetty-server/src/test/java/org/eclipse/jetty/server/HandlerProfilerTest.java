@@ -0,0 +1,146 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.util.List;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.server.handler.StatisticsHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+import static org.junit.jupiter.api.Assumptions.assumeTrue;
+
+public class HandlerProfilerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private HandlerProfiler profiler;
+    private StatisticsHandler statisticsHandler;
+    private HandlerWrapper wrapper;
+    private AllocatingHandler allocatingHandler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        profiler = new HandlerProfiler();
+        server.setHandlerInstrumentation(profiler);
+
+        statisticsHandler = new StatisticsHandler();
+        ContextHandler context = new ContextHandler("/ctx");
+        wrapper = new HandlerWrapper();
+        allocatingHandler = new AllocatingHandler();
+        wrapper.setHandler(allocatingHandler);
+        context.setHandler(wrapper);
+        statisticsHandler.setHandler(context);
+        server.setHandler(statisticsHandler);
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    @Test
+    public void testProfiles() throws Exception
+    {
+        assumeTrue(profiler.isAllocationSupported());
+
+        int requests = 5;
+        for (int i = 0; i < requests; ++i)
+        {
+            HttpTester.Response response = HttpTester.parseResponse(connector.getResponse("GET /ctx/ HTTP/1.1\r\nHost: localhost\r\n\r\n"));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        }
+
+        HandlerProfiler.Profile allocating = profiler.getProfile(allocatingHandler);
+        assertThat(allocating, notNullValue());
+        assertThat(allocating.getInvocations(), is((long)requests));
+        assertThat(allocating.getSelfAllocatedBytes(), greaterThanOrEqualTo((long)requests * AllocatingHandler.BYTES));
+
+        // The wrapper allocates little by itself, but includes the nested handler allocations.
+        HandlerProfiler.Profile wrapping = profiler.getProfile(wrapper);
+        assertThat(wrapping, notNullValue());
+        assertThat(wrapping.getAllocatedBytes(), greaterThanOrEqualTo(allocating.getAllocatedBytes()));
+        assertThat(wrapping.getSelfAllocatedBytes(), lessThan(allocating.getSelfAllocatedBytes()));
+
+        // The StatisticsHandler reports the profiles of its descendants.
+        List<HandlerProfiler.Profile> profiles = statisticsHandler.getHandlerProfiles();
+        assertTrue(profiles.contains(allocating));
+        assertTrue(profiles.contains(wrapping));
+        assertThat(statisticsHandler.toStatsHTML(), containsString(allocating.getName()));
+        assertThat(server.dump(), containsString(allocating.getName()));
+
+        profiler.reset();
+        assertTrue(profiler.getProfiles().isEmpty());
+    }
+
+    @Test
+    public void testFailure() throws Exception
+    {
+        allocatingHandler.fail = true;
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse("GET /ctx/ HTTP/1.1\r\nHost: localhost\r\n\r\n"));
+        assertThat(response.getStatus(), is(HttpStatus.INTERNAL_SERVER_ERROR_500));
+
+        assertThat(profiler.getProfile(allocatingHandler).getFailures(), is(1L));
+        assertThat(profiler.getProfile(wrapper).getFailures(), is(1L));
+    }
+
+    @Test
+    public void testNoProfiler()
+    {
+        server.setHandlerInstrumentation(null);
+        assertFalse(server.getBeans().contains(profiler));
+        assertTrue(statisticsHandler.getHandlerProfiles().isEmpty());
+    }
+
+    private static class AllocatingHandler extends AbstractHandler
+    {
+        private static final int BYTES = 1024 * 1024;
+
+        private volatile boolean fail;
+        private volatile byte[] bytes;
+
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+        {
+            bytes = new byte[BYTES];
+            if (fail)
+                throw new ServletException("explicitly_thrown_by_test");
+            baseRequest.setHandled(true);
+        }
+    }
+}
@@ -0,0 +1,159 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector.LocalEndPoint;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.NanoTime;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.hamcrest.Matchers.nullValue;
+
+public class HttpConnectionKeepAliveTest
+{
+    private static final String REQUEST = "GET /%d HTTP/1.1\r\nHost: localhost\r\n\r\n";
+
+    private Server server;
+    private LocalConnector connector;
+    private HttpConfiguration config;
+    private volatile long sleep;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        config = new HttpConfiguration();
+        connector = new LocalConnector(server, new HttpConnectionFactory(config));
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws InterruptedException
+            {
+                baseRequest.setHandled(true);
+                if (sleep > 0)
+                    Thread.sleep(sleep);
+                response.getWriter().print(target);
+            }
+        });
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    @Test
+    public void testMaxRequestsPerConnection() throws Exception
+    {
+        config.setMaxRequestsPerConnection(3);
+        config.setSendKeepAliveHeader(true);
+        long idleTimeout = TimeUnit.MILLISECONDS.toSeconds(connector.getIdleTimeout());
+
+        LocalEndPoint endPoint = connector.executeRequest(String.format(REQUEST, 1));
+        for (int i = 1; i <= 3; ++i)
+        {
+            HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.getContent(), is("/" + i));
+            if (i < 3)
+            {
+                assertThat(response.get(HttpHeader.CONNECTION), nullValue());
+                assertThat(response.get(HttpHeader.KEEP_ALIVE), is("timeout=" + idleTimeout + ", max=" + (3 - i)));
+                endPoint.addInputAndExecute(BufferUtil.toBuffer(String.format(REQUEST, i + 1)));
+            }
+            else
+            {
+                // The last allowed request closes the connection.
+                assertThat(response.get(HttpHeader.CONNECTION), is("close"));
+                assertThat(response.get(HttpHeader.KEEP_ALIVE), nullValue());
+            }
+        }
+
+        endPoint.waitUntilClosed();
+        assertThat(endPoint.isOpen(), is(false));
+    }
+
+    @Test
+    public void testMaxRequestsPerConnectionHttp10() throws Exception
+    {
+        config.setMaxRequestsPerConnection(2);
+
+        LocalEndPoint endPoint = connector.executeRequest("GET /1 HTTP/1.0\r\nConnection: keep-alive\r\n\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONNECTION), is("keep-alive"));
+
+        endPoint.addInputAndExecute(BufferUtil.toBuffer("GET /2 HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"));
+        response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONNECTION), nullValue());
+
+        endPoint.waitUntilClosed();
+        assertThat(endPoint.isOpen(), is(false));
+    }
+
+    @Test
+    public void testIdleConnectionClosedAtMaxAge() throws Exception
+    {
+        long maxAge = 500;
+        config.setMaxConnectionAge(maxAge);
+        config.setMaxConnectionAgeJitter(maxAge / 2);
+
+        long begin = NanoTime.now();
+        LocalEndPoint endPoint = connector.executeRequest(String.format(REQUEST, 1));
+        HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONNECTION), nullValue());
+
+        endPoint.waitUntilClosed();
+        assertThat(endPoint.isOpen(), is(false));
+        long elapsed = NanoTime.millisSince(begin);
+        // The jitter shortens the max age by at most half.
+        assertThat(elapsed, greaterThanOrEqualTo(maxAge / 2));
+        assertThat(elapsed, lessThan(connector.getIdleTimeout()));
+    }
+
+    @Test
+    public void testBusyConnectionClosedAfterResponseAtMaxAge() throws Exception
+    {
+        config.setMaxConnectionAge(250);
+        sleep = 500;
+
+        LocalEndPoint endPoint = connector.executeRequest(String.format(REQUEST, 1));
+        HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+        // The response is complete, although the max age expired while it was being generated.
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("/1"));
+
+        endPoint.waitUntilClosed();
+        assertThat(endPoint.isOpen(), is(false));
+    }
+}
@@ -0,0 +1,135 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector.LocalEndPoint;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+public class HttpConnectionPipelineTest
+{
+    private static final String REQUEST = "GET /%d HTTP/1.1\r\nHost: localhost\r\n\r\n";
+
+    private final List<Integer> depths = new CopyOnWriteArrayList<>();
+    private final List<Long> blocked = new CopyOnWriteArrayList<>();
+    private final AtomicReference<HttpConnection> connection = new AtomicReference<>();
+    private Server server;
+    private LocalConnector connector;
+    private HttpConfiguration config;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        config = new HttpConfiguration();
+        connector = new LocalConnector(server, new HttpConnectionFactory(config));
+        connector.addBean((HttpConnection.PipelineListener)(connection, depth, blockedMillis) ->
+        {
+            this.connection.set(connection);
+            depths.add(depth);
+            blocked.add(blockedMillis);
+        });
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws InterruptedException
+            {
+                baseRequest.setHandled(true);
+                // Slow handling so that the pipelined requests are blocked.
+                Thread.sleep(50);
+                response.getWriter().print(target);
+            }
+        });
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    @Test
+    public void testPipelinedRequestsAreCounted() throws Exception
+    {
+        LocalEndPoint endPoint = connector.executeRequest(String.format(REQUEST + REQUEST + REQUEST, 1, 2, 3));
+
+        for (int i = 1; i <= 3; ++i)
+        {
+            HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.getContent(), is("/" + i));
+        }
+
+        assertThat(depths, is(List.of(1, 2)));
+        assertThat(blocked.get(0), greaterThanOrEqualTo(50L));
+        assertThat(blocked.get(1), greaterThanOrEqualTo(50L));
+        HttpConnection httpConnection = connection.get();
+        assertThat(httpConnection.getPipelinedRequests(), is(2L));
+        assertThat(httpConnection.getMaxPipelineDepth(), is(2));
+        assertThat(httpConnection.getPipelineBlockedTime(), greaterThanOrEqualTo(100L));
+        assertThat(httpConnection.toConnectionString(), containsString("pipelined=2"));
+
+        // The last request was not followed by a pipelined request.
+        await().atMost(5, TimeUnit.SECONDS).until(httpConnection::getPipelineDepth, is(0));
+
+        endPoint.addInputAndExecute(BufferUtil.toBuffer(String.format(REQUEST, 4)));
+        assertThat(HttpTester.parseResponse(endPoint.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(httpConnection.getPipelinedRequests(), is(2L));
+        assertThat(httpConnection.getMaxPipelineDepth(), is(2));
+    }
+
+    @Test
+    public void testMaxPipelinedRequestsExceeded() throws Exception
+    {
+        config.setMaxPipelinedRequests(1);
+
+        LocalEndPoint endPoint = connector.executeRequest(String.format(REQUEST + REQUEST + REQUEST, 1, 2, 3));
+
+        HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONNECTION), nullValue());
+
+        response = HttpTester.parseResponse(endPoint.getResponse());
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(response.get(HttpHeader.CONNECTION), is("close"));
+
+        endPoint.waitUntilClosed();
+        assertThat(endPoint.isOpen(), is(false));
+        assertThat(endPoint.getResponse(false, 1, TimeUnit.SECONDS), nullValue());
+    }
+}
@@ -40,6 +40,7 @@ import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.HttpCompliance;
+import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpParser;
 import org.eclipse.jetty.http.HttpStatus;
@@ -884,6 +885,56 @@ public class HttpConnectionTest
         assertThat(response, Matchers.containsString("200 OK"));
     }
 
+    @Test
+    public void testEarlyHints() throws Exception
+    {
+        server.stop();
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                Response jettyResponse = baseRequest.getResponse();
+                boolean sent = jettyResponse.sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</style.css>; rel=preload; as=style"));
+                sent &= jettyResponse.sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</script.js>; rel=preload; as=script"));
+                response.setHeader("X-Sent", String.valueOf(sent));
+                response.setStatus(200);
+                response.getWriter().print("OK");
+            }
+        });
+        server.start();
+
+        String response = connector.getResponse("GET / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Connection: close\r\n" +
+            "\r\n");
+
+        int first = response.indexOf("HTTP/1.1 103 ");
+        int second = response.indexOf("HTTP/1.1 103 ", first + 1);
+        int last = response.indexOf("HTTP/1.1 200 ");
+        assertThat(first, is(0));
+        assertThat(second, greaterThan(first));
+        assertThat(last, greaterThan(second));
+        assertThat(response.substring(first, second), containsString("Link: </style.css>; rel=preload; as=style"));
+        assertThat(response.substring(first, second), not(containsString("script.js")));
+        assertThat(response.substring(second, last), containsString("Link: </script.js>; rel=preload; as=script"));
+        // The hints are not part of the final response.
+        HttpTester.Response finalResponse = HttpTester.parseResponse(response.substring(last));
+        assertThat(finalResponse.get(HttpHeader.LINK), Matchers.nullValue());
+        assertThat(finalResponse.get("X-Sent"), is("true"));
+        assertThat(finalResponse.getContent(), is("OK"));
+
+        // HTTP/1.0 clients cannot process interim responses.
+        response = connector.getResponse("GET / HTTP/1.0\r\n" +
+            "Host: localhost\r\n" +
+            "\r\n");
+        assertThat(response, not(containsString(" 103 ")));
+        finalResponse = HttpTester.parseResponse(response);
+        assertThat(finalResponse.getStatus(), is(200));
+        assertThat(finalResponse.get("X-Sent"), is("false"));
+    }
+
     @Test
     public void testCharset() throws Exception
     {
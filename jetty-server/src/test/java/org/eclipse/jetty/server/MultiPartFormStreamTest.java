@@ -0,0 +1,235 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.function.Consumer;
+import javax.servlet.AsyncContext;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class MultiPartFormStreamTest
+{
+    private static final String BOUNDARY = "XyXyXy";
+
+    public WorkDir workDir;
+    private final List<Path> _paths = new CopyOnWriteArrayList<>();
+    private Path _tempDir;
+    private Server _server;
+    private LocalConnector _connector;
+
+    @BeforeEach
+    public void prepare()
+    {
+        _tempDir = workDir.getEmptyPathDir();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (_server != null)
+            _server.stop();
+    }
+
+    private void start(Consumer<MultiPartFormStream> configurer, Path moveDir) throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                AsyncContext asyncContext = request.startAsync();
+                MultiPartFormStream multiPart = new MultiPartFormStream(request);
+                multiPart.setTempDirectory(_tempDir);
+                configurer.accept(multiPart);
+                StringBuilder result = new StringBuilder();
+                multiPart.parse(new MultiPartFormStream.Listener()
+                {
+                    private MultiPartFormStream.PathSink sink;
+
+                    @Override
+                    public MultiPartFormStream.Sink onPart(MultiPartFormStream.Part part) throws IOException
+                    {
+                        appendLast();
+                        result.append(part.getName()).append("=");
+                        sink = multiPart.newPathSink();
+                        _paths.add(sink.getPath());
+                        return sink;
+                    }
+
+                    private void appendLast()
+                    {
+                        if (sink == null)
+                            return;
+                        try
+                        {
+                            result.append(Files.readString(sink.getPath())).append("\n");
+                            if (moveDir != null)
+                                _paths.add(sink.moveTo(moveDir.resolve(String.valueOf(_paths.size()))));
+                        }
+                        catch (IOException x)
+                        {
+                            throw new RuntimeException(x);
+                        }
+                    }
+
+                    @Override
+                    public void onComplete()
+                    {
+                        try
+                        {
+                            appendLast();
+                            response.setStatus(HttpStatus.OK_200);
+                            response.getWriter().print(result);
+                        }
+                        catch (IOException x)
+                        {
+                            response.setStatus(HttpStatus.INTERNAL_SERVER_ERROR_500);
+                        }
+                        asyncContext.complete();
+                    }
+
+                    @Override
+                    public void onFailure(Throwable failure)
+                    {
+                        response.setStatus(HttpStatus.PAYLOAD_TOO_LARGE_413);
+                        asyncContext.complete();
+                    }
+                });
+            }
+        });
+        _server.start();
+    }
+
+    private HttpTester.Response post(String... parts) throws Exception
+    {
+        StringBuilder content = new StringBuilder();
+        for (int i = 0; i < parts.length; ++i)
+        {
+            content.append("--").append(BOUNDARY).append("\r\n");
+            content.append("Content-Disposition: form-data; name=\"part").append(i).append("\"\r\n");
+            content.append("\r\n");
+            content.append(parts[i]).append("\r\n");
+        }
+        content.append("--").append(BOUNDARY).append("--\r\n");
+        byte[] bytes = content.toString().getBytes(StandardCharsets.UTF_8);
+
+        String request = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Type: multipart/form-data; boundary=" + BOUNDARY + "\r\n" +
+            "Content-Length: " + bytes.length + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            content;
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    private boolean awaitDeleted() throws InterruptedException
+    {
+        for (int i = 0; i < 50; ++i)
+        {
+            if (_paths.stream().noneMatch(Files::exists))
+                return true;
+            Thread.sleep(100);
+        }
+        return false;
+    }
+
+    @Test
+    public void testPartsStreamedToFilesAndDeletedOnCompletion() throws Exception
+    {
+        start(multiPart -> {}, null);
+
+        HttpTester.Response response = post("value", "x".repeat(64 * 1024));
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("part0=value\n"));
+        assertThat(response.getContent(), containsString("part1=" + "x".repeat(64 * 1024) + "\n"));
+        assertThat(_paths.size(), is(2));
+        assertTrue(awaitDeleted());
+    }
+
+    @Test
+    public void testMovedFilesAreNotDeleted() throws Exception
+    {
+        Path moveDir = Files.createDirectories(workDir.getPath().resolve("moved"));
+        start(multiPart -> {}, moveDir);
+
+        HttpTester.Response response = post("one", "two");
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(Files.readString(moveDir.resolve("1")), is("one"));
+        assertThat(Files.readString(moveDir.resolve("3")), is("two"));
+    }
+
+    @Test
+    public void testMaxPartSize() throws Exception
+    {
+        start(multiPart -> multiPart.setMaxPartSize(1024), null);
+
+        HttpTester.Response response = post("small", "x".repeat(2048));
+
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertTrue(awaitDeleted());
+    }
+
+    @Test
+    public void testMaxContentSize() throws Exception
+    {
+        start(multiPart -> multiPart.setMaxContentSize(1024), null);
+
+        HttpTester.Response response = post("x".repeat(600), "x".repeat(600));
+
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertTrue(awaitDeleted());
+    }
+
+    @Test
+    public void testMaxParts() throws Exception
+    {
+        start(multiPart -> multiPart.setMaxParts(2), null);
+
+        HttpTester.Response response = post("a", "b", "c");
+
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertFalse(_paths.size() > 2);
+        assertTrue(awaitDeleted());
+    }
+}
@@ -0,0 +1,142 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.Base64;
+import java.util.Random;
+import java.util.concurrent.atomic.AtomicBoolean;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class MultiPartResponseTest
+{
+    @Test
+    public void testEmpty() throws Exception
+    {
+        MultiPartResponse multiPart = new MultiPartResponse("multipart/mixed", "XyXy");
+        ByteArrayOutputStream out = new ByteArrayOutputStream();
+        multiPart.writeTo(out);
+
+        assertEquals("--XyXy--\r\n", out.toString(StandardCharsets.ISO_8859_1));
+        assertEquals(out.size(), multiPart.getLength());
+    }
+
+    @Test
+    public void testKnownLength() throws Exception
+    {
+        MultiPartResponse multiPart = new MultiPartResponse("multipart/mixed", "XyXy");
+        multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "text/plain"), "hello".getBytes(StandardCharsets.UTF_8));
+        multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "application/json"), 2, () -> new ByteArrayInputStream("{}".getBytes(StandardCharsets.UTF_8)));
+        ByteArrayOutputStream out = new ByteArrayOutputStream();
+        multiPart.writeTo(out);
+
+        assertEquals("multipart/mixed; boundary=XyXy", multiPart.getContentType());
+        assertEquals(
+            "--XyXy\r\n" +
+            "Content-Type: text/plain\r\n" +
+            "\r\n" +
+            "hello\r\n" +
+            "--XyXy\r\n" +
+            "Content-Type: application/json\r\n" +
+            "\r\n" +
+            "{}\r\n" +
+            "--XyXy--\r\n", out.toString(StandardCharsets.ISO_8859_1));
+        assertEquals(out.size(), multiPart.getLength());
+    }
+
+    @Test
+    public void testBase64TransferEncoding() throws Exception
+    {
+        byte[] data = new byte[1000];
+        new Random().nextBytes(data);
+        MultiPartResponse multiPart = new MultiPartResponse("multipart/mixed", "XyXy");
+        multiPart.addPart(HttpFields.build()
+            .put(HttpHeader.CONTENT_TYPE, "application/octet-stream")
+            .put("Content-Transfer-Encoding", "base64"), data);
+        ByteArrayOutputStream out = new ByteArrayOutputStream();
+        multiPart.writeTo(out);
+        assertEquals(out.size(), multiPart.getLength());
+
+        String content = out.toString(StandardCharsets.ISO_8859_1);
+        int start = content.indexOf("\r\n\r\n") + 4;
+        int end = content.lastIndexOf("\r\n--XyXy--");
+        String encoded = content.substring(start, end);
+        assertTrue(encoded.contains("\r\n"));
+        assertArrayEquals(data, Base64.getMimeDecoder().decode(encoded));
+
+        assertThrows(IllegalArgumentException.class, () -> multiPart.addPart(HttpFields.build().put("Content-Transfer-Encoding", "x-unknown"), data));
+    }
+
+    @Test
+    public void testUnknownLengthIsChunked() throws Exception
+    {
+        AtomicBoolean opened = new AtomicBoolean();
+        Server server = new Server();
+        LocalConnector connector = new LocalConnector(server);
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                MultiPartResponse multiPart = new MultiPartResponse();
+                multiPart.addPart(HttpFields.build().put(HttpHeader.CONTENT_TYPE, "text/plain"), () ->
+                {
+                    opened.set(true);
+                    return new ByteArrayInputStream("streamed".getBytes(StandardCharsets.UTF_8));
+                });
+                assertFalse(opened.get());
+                assertEquals(-1, multiPart.getLength());
+                multiPart.writeTo(response);
+            }
+        });
+        server.start();
+        try
+        {
+            HttpTester.Response response = HttpTester.parseResponse(connector.getResponse("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"));
+            assertThat(response.getStatus(), is(HttpStatus.OK_200));
+            assertThat(response.get(HttpHeader.CONTENT_TYPE), containsString("multipart/mixed; boundary=jetty"));
+            assertThat(response.get(HttpHeader.CONTENT_LENGTH), nullValue());
+            assertThat(response.get(HttpHeader.TRANSFER_ENCODING), is("chunked"));
+            assertThat(response.getContent(), containsString("streamed"));
+            assertTrue(opened.get());
+        }
+        finally
+        {
+            server.stop();
+        }
+    }
+}
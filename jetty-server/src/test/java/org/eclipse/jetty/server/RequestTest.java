@@ -35,6 +35,7 @@ import java.util.Enumeration;
 import java.util.List;
 import java.util.Locale;
 import java.util.Map;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.AtomicReference;
@@ -51,6 +52,7 @@ import javax.servlet.http.Part;
 import javax.servlet.http.PushBuilder;
 
 import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.ComplianceViolation;
 import org.eclipse.jetty.http.CookieCompliance;
 import org.eclipse.jetty.http.HttpCompliance;
 import org.eclipse.jetty.http.HttpCookie;
@@ -63,6 +65,7 @@ import org.eclipse.jetty.http.HttpURI;
 import org.eclipse.jetty.http.HttpVersion;
 import org.eclipse.jetty.http.MetaData;
 import org.eclipse.jetty.http.MimeTypes;
+import org.eclipse.jetty.http.UriCanonicalizer;
 import org.eclipse.jetty.http.UriCompliance;
 import org.eclipse.jetty.http.pathmap.MatchedPath;
 import org.eclipse.jetty.http.pathmap.RegexPathSpec;
@@ -2005,6 +2008,49 @@ public class RequestTest
         assertThat(_connector.getResponse(request), startsWith("HTTP/1.1 400"));
     }
 
+    @Test
+    public void testUriCanonicalizer() throws Exception
+    {
+        _handler._checker = (request, response) ->
+        {
+            response.getOutputStream().println("pathInfo=" + request.getPathInfo());
+            return true;
+        };
+        List<ComplianceViolation.Event> events = new CopyOnWriteArrayList<>();
+        UriCanonicalizer canonicalizer = new UriCanonicalizer();
+        canonicalizer.addListener(new ComplianceViolation.Listener()
+        {
+            @Override
+            public void onComplianceViolation(ComplianceViolation.Event event)
+            {
+                events.add(event);
+            }
+        });
+        HttpConfiguration httpConfiguration = _connector.getBean(HttpConnectionFactory.class).getHttpConfiguration();
+        // Allow all the ambiguities, the canonicalizer rejects the dangerous ones.
+        httpConfiguration.setUriCompliance(UriCompliance.RFC3986);
+        httpConfiguration.setUriCanonicalizer(canonicalizer);
+
+        String response = _connector.getResponse("GET /foo/%62ar/./baz HTTP/1.0\r\nHost: whatever\r\n\r\n");
+        assertThat(response, startsWith("HTTP/1.1 200"));
+        assertThat(response, containsString("pathInfo=/foo/bar/baz"));
+        assertThat(events.get(0).getViolation(), is(UriCanonicalizer.Violation.DOT_SEGMENT));
+
+        String[] rejected = {
+            "/ambiguous%2f%2e%2e/secret",
+            "/ambiguous/..;/secret",
+            "/overlong/%c0%ae%c0%ae/secret",
+            "/backslash%5c..%5csecret",
+            "/double/%252e%252e/secret"
+        };
+        for (String path : rejected)
+        {
+            events.clear();
+            assertThat(path, _connector.getResponse("GET " + path + " HTTP/1.0\r\nHost: whatever\r\n\r\n"), startsWith("HTTP/1.1 400"));
+            assertThat(path, events.get(events.size() - 1).isAllowed(), is(false));
+        }
+    }
+
     @Test
     public void testAmbiguousDoubleSlash() throws Exception
     {
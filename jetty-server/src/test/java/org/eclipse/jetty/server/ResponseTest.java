@@ -2177,6 +2177,113 @@ public class ResponseTest
         assertEquals("Foo=other; SameSite=Lax", set);
     }
 
+    @Test
+    public void testAddCookieServerAndContextDefaults()
+    {
+        Response response = getResponse();
+        HttpConfiguration config = response.getHttpChannel().getHttpConfiguration();
+        config.setCookieSameSiteDefault("none");
+        config.setCookieSecureDefault(true);
+        config.setCookiePartitionedDefault(true);
+
+        // The Secure default only applies to secure requests.
+        response.addCookie(new HttpCookie("plain", "value"));
+        assertEquals("plain=value; SameSite=None", response.getHttpFields().get("Set-Cookie"));
+        response.getHttpFields().remove("Set-Cookie");
+
+        _channel.getRequest().setSecure(true);
+        response.addCookie(new HttpCookie("secure", "value"));
+        assertEquals("secure=value; Secure; SameSite=None; Partitioned", response.getHttpFields().get("Set-Cookie"));
+        response.getHttpFields().remove("Set-Cookie");
+
+        // Explicit attributes are not overridden, and only SameSite=None cookies are partitioned.
+        response.addCookie(HttpCookie.build("lax", "value").sameSite(HttpCookie.SameSite.LAX).build());
+        assertEquals("lax=value; Secure; SameSite=Lax", response.getHttpFields().get("Set-Cookie"));
+        response.getHttpFields().remove("Set-Cookie");
+
+        // The context defaults override the server defaults.
+        TestServletContextHandler context = new TestServletContextHandler();
+        context.setAttribute(HttpCookie.SAME_SITE_DEFAULT_ATTRIBUTE, "STRICT");
+        context.setAttribute(HttpCookie.SECURE_DEFAULT_ATTRIBUTE, "false");
+        _channel.getRequest().setContext(context.getServletContext(), "/");
+        response.addCookie(new HttpCookie("context", "value"));
+        assertEquals("context=value; SameSite=Strict", response.getHttpFields().get("Set-Cookie"));
+        response.getHttpFields().remove("Set-Cookie");
+
+        // Partitioned can be set from the servlet cookie comment.
+        Cookie cookie = new Cookie("chips", "value");
+        cookie.setSecure(true);
+        cookie.setComment("__SAME_SITE_NONE____PARTITIONED__");
+        response.addCookie(cookie);
+        assertEquals("chips=value; Secure; SameSite=None; Partitioned", response.getHttpFields().get("Set-Cookie"));
+    }
+
+    @Test
+    public void testAddCookiePrefix()
+    {
+        Response response = getResponse();
+
+        // The default compliance does not check the prefixes.
+        response.addCookie(new HttpCookie("__Host-name", "value"));
+        assertEquals("__Host-name=value", response.getHttpFields().get("Set-Cookie"));
+        response.getHttpFields().remove("Set-Cookie");
+
+        response.getHttpChannel().getHttpConfiguration().setResponseCookieCompliance(CookieCompliance.RFC6265BIS);
+        assertThrows(IllegalArgumentException.class, () -> response.addCookie(new HttpCookie("__Host-name", "value")));
+        assertThrows(IllegalArgumentException.class, () -> response.addCookie(new HttpCookie("__Secure-name", "value")));
+        assertNull(response.getHttpFields().get("Set-Cookie"));
+
+        response.addCookie(HttpCookie.build("__Host-name", "value").path("/").secure(true).build());
+        assertEquals("__Host-name=value; Path=/; Secure", response.getHttpFields().get("Set-Cookie"));
+    }
+
+    @Test
+    public void testRewriteCookies()
+    {
+        Response response = getResponse();
+        response.addCookie(new HttpCookie("keep", "1"));
+        response.addCookie(new HttpCookie("remove", "2"));
+        response.getHttpFields().add(HttpHeader.SET_COOKIE, "parsed=3; Path=/");
+
+        List<HttpCookie> cookies = response.getHttpCookies();
+        assertThat(cookies.size(), is(3));
+        assertThat(cookies.get(2).getName(), is("parsed"));
+        assertThat(cookies.get(2).getPath(), is("/"));
+
+        response.rewriteCookies(cookie ->
+        {
+            switch (cookie.getName())
+            {
+                case "remove":
+                    return null;
+                case "parsed":
+                    return HttpCookie.build(cookie).httpOnly(true).build();
+                default:
+                    return cookie;
+            }
+        });
+
+        List<String> actual = Collections.list(response.getHttpFields().getValues("Set-Cookie"));
+        assertThat(actual, containsInAnyOrder("keep=1", "parsed=3; Path=/; HttpOnly"));
+    }
+
+    @Test
+    public void testRewriteCookiesSkipsUnparseableHeaders()
+    {
+        Response response = getResponse();
+        response.addCookie(new HttpCookie("keep", "1"));
+        // Two cookies in the same header value cannot be parsed as a single cookie.
+        String multiple = "a=1; Version=1, b=2; Version=1";
+        response.getHttpFields().add(HttpHeader.SET_COOKIE, multiple);
+
+        assertThat(response.getHttpCookies().size(), is(1));
+
+        response.rewriteCookies(cookie -> HttpCookie.build(cookie).httpOnly(true).build());
+
+        List<String> actual = Collections.list(response.getHttpFields().getValues("Set-Cookie"));
+        assertThat(actual, containsInAnyOrder("keep=1; HttpOnly", multiple));
+    }
+
     @Test
     public void testReplaceParsedHttpCookie()
     {
@@ -0,0 +1,185 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.util.Collections;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class RuleBasedHeaderPolicyTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private RuleBasedHeaderPolicy policy;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        policy = new RuleBasedHeaderPolicy();
+        connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().setHeaderPolicy(policy);
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                for (String name : Collections.list(request.getHeaderNames()))
+                {
+                    response.getWriter().printf("%s: %s%n", name, request.getHeader(name));
+                }
+            }
+        });
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response request(String headers) throws Exception
+    {
+        return HttpTester.parseResponse(connector.getResponse("GET / HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n"));
+    }
+
+    @Test
+    public void testDrop() throws Exception
+    {
+        RuleBasedHeaderPolicy.Rule rule = policy.drop("X-Internal-*");
+        server.start();
+
+        HttpTester.Response response = request("X-Internal-User: admin\r\nx-internal-role: root\r\nX-Other: value\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), not(containsString("admin")));
+        assertThat(response.getContent(), not(containsString("root")));
+        assertThat(response.getContent(), containsString("X-Other: value"));
+        assertThat(rule.getCount(), is(2L));
+    }
+
+    @Test
+    public void testDropByValue() throws Exception
+    {
+        RuleBasedHeaderPolicy.Rule rule = policy.drop("X-Debug", "(?i)true");
+        server.start();
+
+        assertThat(request("X-Debug: TRUE\r\n").getContent(), not(containsString("X-Debug")));
+        assertThat(request("X-Debug: false\r\n").getContent(), containsString("X-Debug: false"));
+        assertThat(rule.getCount(), is(1L));
+    }
+
+    @Test
+    public void testReject() throws Exception
+    {
+        RuleBasedHeaderPolicy.Rule rule = policy.reject("Transfer-Encoding", null);
+        server.start();
+
+        assertThat(request("").getStatus(), is(HttpStatus.OK_200));
+        HttpTester.Response response = request("Transfer-Encoding: chunked\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(rule.getCount(), is(1L));
+    }
+
+    @Test
+    public void testMaxHeaders() throws Exception
+    {
+        RuleBasedHeaderPolicy.Rule cookies = policy.maxCount("Cookie", 1);
+        RuleBasedHeaderPolicy.Rule headers = policy.maxHeaders(4);
+        server.start();
+
+        assertThat(request("A: 1\r\nB: 2\r\n").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("A: 1\r\nB: 2\r\nC: 3\r\nD: 4\r\n").getStatus(), is(HttpStatus.REQUEST_HEADER_FIELDS_TOO_LARGE_431));
+        assertThat(headers.getCount(), is(1L));
+        assertThat(request("Cookie: a=1\r\nCookie: b=2\r\n").getStatus(), is(HttpStatus.REQUEST_HEADER_FIELDS_TOO_LARGE_431));
+        assertThat(cookies.getCount(), is(1L));
+    }
+
+    @Test
+    public void testNormalize() throws Exception
+    {
+        RuleBasedHeaderPolicy.Rule normalize = policy.normalize();
+        // Rules after normalization see the normalized fields.
+        RuleBasedHeaderPolicy.Rule reject = policy.reject("X-Mode", "a b");
+        server.start();
+
+        HttpTester.Response response = request("x-custom-header: one \t  two\r\naccept-language: en\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("X-Custom-Header: one two"));
+        assertThat(response.getContent(), containsString("Accept-Language: en"));
+        assertThat(normalize.getCount(), greaterThanOrEqualTo(1L));
+
+        assertThat(request("X-Mode: a    b\r\n").getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(reject.getCount(), is(1L));
+
+        policy.resetCounts();
+        assertThat(normalize.getCount(), is(0L));
+    }
+
+    @Test
+    public void testFramingHeadersCannotBeDropped()
+    {
+        assertThrows(IllegalArgumentException.class, () -> policy.drop("Content-Length"));
+        assertThrows(IllegalArgumentException.class, () -> policy.drop("transfer-*"));
+        assertThrows(IllegalArgumentException.class, () -> policy.drop("*"));
+        assertThat(policy.getRules().size(), is(0));
+    }
+
+    @Test
+    public void testFramingHeadersAreNotModifiedByPolicy() throws Exception
+    {
+        // A policy that tries to drop all the header fields.
+        connector.getConnectionFactory(HttpConnectionFactory.class).getHttpConfiguration().setHeaderPolicy(field -> null);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                response.getWriter().printf("length=%d host=%s content=%s", request.getContentLength(), request.getHeader("Host"), IO.toString(request.getInputStream()));
+            }
+        });
+        server.start();
+
+        String rawRequest = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Length: 5\r\n" +
+            "X-Dropped: value\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            "hello";
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(rawRequest));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("length=5 host=localhost content=hello"));
+    }
+}
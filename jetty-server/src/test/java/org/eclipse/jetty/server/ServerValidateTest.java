@@ -0,0 +1,101 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.net.InetAddress;
+import java.net.ServerSocket;
+import java.util.List;
+
+import org.eclipse.jetty.toolchain.test.MavenTestingUtils;
+import org.eclipse.jetty.util.component.ValidationReport;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.sameInstance;
+
+public class ServerValidateTest
+{
+    @Test
+    public void testValidConfiguration() throws Exception
+    {
+        Server server = new Server();
+        ServerConnector connector = new ServerConnector(server);
+        server.addConnector(connector);
+        SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStorePath(MavenTestingUtils.getTestResourceFile("keystore.p12").getAbsolutePath());
+        sslContextFactory.setKeyStorePassword("storepwd");
+        ServerConnector tlsConnector = new ServerConnector(server, sslContextFactory);
+        server.addConnector(tlsConnector);
+
+        ValidationReport report = server.validate();
+
+        assertThat(report.toString(), report.isValid(), is(true));
+        // Nothing is left open or started.
+        assertThat(connector.isOpen(), is(false));
+        assertThat(tlsConnector.isOpen(), is(false));
+        assertThat(sslContextFactory.isStopped(), is(true));
+        assertThat(server.isStopped(), is(true));
+    }
+
+    @Test
+    public void testAllProblemsAreReported() throws Exception
+    {
+        try (ServerSocket busy = new ServerSocket(0, 50, InetAddress.getLoopbackAddress()))
+        {
+            Server server = new Server();
+            ServerConnector connector = new ServerConnector(server);
+            connector.setHost(busy.getInetAddress().getHostAddress());
+            connector.setPort(busy.getLocalPort());
+            server.addConnector(connector);
+            SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+            sslContextFactory.setKeyStorePath(MavenTestingUtils.getTargetPath("missing.p12").toString());
+            sslContextFactory.setKeyStorePassword("storepwd");
+            ServerConnector tlsConnector = new ServerConnector(server, sslContextFactory);
+            server.addConnector(tlsConnector);
+
+            ValidationReport report = server.validate();
+
+            assertThat(report.isValid(), is(false));
+            List<ValidationReport.Problem> problems = report.getProblems();
+            assertThat(report.toString(), problems, hasSize(2));
+            assertThat(problems.get(0).getSource(), sameInstance(connector));
+            assertThat(problems.get(0).getMessage(), containsString("Failed to bind"));
+            assertThat(problems.get(1).getSource(), sameInstance(sslContextFactory));
+            assertThat(report.toString(), containsString("2 problem(s)"));
+        }
+    }
+
+    @Test
+    public void testStartedConnectorIsNotRebound() throws Exception
+    {
+        Server server = new Server();
+        ServerConnector connector = new ServerConnector(server);
+        server.addConnector(connector);
+        server.start();
+        try
+        {
+            ValidationReport report = server.validate();
+            assertThat(report.toString(), report.isValid(), is(true));
+            assertThat(connector.isOpen(), is(true));
+        }
+        finally
+        {
+            server.stop();
+        }
+    }
+}
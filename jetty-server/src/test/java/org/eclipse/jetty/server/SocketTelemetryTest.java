@@ -0,0 +1,110 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.OutputStream;
+import java.net.Socket;
+import java.nio.channels.SocketChannel;
+import java.nio.charset.StandardCharsets;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.io.SocketTelemetry;
+import org.eclipse.jetty.io.TcpInfo;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class SocketTelemetryTest
+{
+    private Server server;
+    private ServerConnector connector;
+    private SocketTelemetry telemetry;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server, 1, 1);
+        telemetry = new SocketTelemetry();
+        // Sample on demand only.
+        telemetry.setSamplePeriod(0);
+        telemetry.setProvider(channels ->
+        {
+            Map<SocketChannel, TcpInfo> result = new HashMap<>();
+            for (SocketChannel channel : channels)
+            {
+                result.put(channel, new TcpInfo(1500, 250, 201000, 2, 10, 0, 0, 65536, 65536));
+            }
+            return result;
+        });
+        connector.addBean(telemetry);
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+            }
+        });
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    @Test
+    public void testSample() throws Exception
+    {
+        try (Socket socket = new Socket("localhost", connector.getLocalPort()))
+        {
+            OutputStream output = socket.getOutputStream();
+            output.write("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+
+            await().atMost(5, TimeUnit.SECONDS).until(() -> telemetry.getConnections() == 1);
+            assertThat(telemetry.getRttAverage(), is(TcpInfo.UNKNOWN));
+
+            telemetry.sample();
+
+            EndPoint endPoint = connector.getConnectedEndPoints().iterator().next();
+            assertThat(endPoint.getTcpInfo(), notNullValue());
+            assertThat(endPoint.getTcpInfo().getRtt(), is(1500L));
+            assertThat(endPoint.toString(), containsString("tcp{rtt=1500/250us"));
+            assertThat(telemetry.getRttAverage(), is(1500L));
+            assertThat(telemetry.getRtoAverage(), is(201000L));
+            assertThat(telemetry.getCongestionWindowAverage(), is(10L));
+            assertThat(telemetry.getRetransmits(), is(2L));
+            assertThat(telemetry.dump(), containsString("tcp{rtt=1500/250us"));
+        }
+
+        await().atMost(5, TimeUnit.SECONDS).until(() -> telemetry.getConnections() == 0);
+        assertThat(telemetry.getRetransmits(), is(TcpInfo.UNKNOWN));
+    }
+}
@@ -0,0 +1,181 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server;
+
+import java.io.IOException;
+import java.io.InterruptedIOException;
+import java.time.Instant;
+import java.util.LinkedHashMap;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BlockingArrayQueue;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.startsWith;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class StructuredRequestLogTest
+{
+    private final BlockingArrayQueue<String> _entries = new BlockingArrayQueue<>();
+    private Server _server;
+    private LocalConnector _connector;
+
+    private void start(RequestLogFormatter formatter) throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        StructuredRequestLog requestLog = new StructuredRequestLog(_entries::add, formatter);
+        requestLog.addField("custom", (request, response) -> request.getAttribute("custom"));
+        _server.setRequestLog(requestLog);
+        _server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                request.setAttribute("custom", "a \"quoted\" value");
+                response.setStatus(202);
+            }
+        });
+        _server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (_server != null)
+            _server.stop();
+    }
+
+    @Test
+    public void testJson() throws Exception
+    {
+        start(new RequestLogFormatter.Json());
+
+        _connector.getResponse("GET /path?a=b HTTP/1.1\r\nHost: localhost\r\nUser-Agent: test\r\nConnection: close\r\n\r\n");
+        String entry = _entries.poll(5, TimeUnit.SECONDS);
+
+        assertThat(entry, startsWith("{\"timestamp\":\""));
+        assertThat(entry, containsString("\"method\":\"GET\""));
+        assertThat(entry, containsString("\"uri\":\"/path?a=b\""));
+        assertThat(entry, containsString("\"status\":202"));
+        assertThat(entry, containsString("\"user_agent\":\"test\""));
+        assertThat(entry, containsString("\"custom\":\"a \\\"quoted\\\" value\""));
+        // Null values are omitted.
+        assertThat(entry, not(containsString("\"referer\"")));
+        assertThat(entry, not(containsString("\"tls.protocol\"")));
+    }
+
+    @Test
+    public void testLogfmt() throws Exception
+    {
+        start(new RequestLogFormatter.Logfmt());
+
+        _connector.getResponse("GET /path HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n");
+        String entry = _entries.poll(5, TimeUnit.SECONDS);
+
+        assertThat(entry, containsString(" method=GET uri=/path protocol=HTTP/1.1 status=202 "));
+        assertThat(entry, containsString("custom=\"a \\\"quoted\\\" value\""));
+    }
+
+    @Test
+    public void testHandlerTimes() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _server.setRequestLog(new StructuredRequestLog(_entries::add, new RequestLogFormatter.Json()));
+        StructuredRequestLog.TimingHandler outer = new StructuredRequestLog.TimingHandler("outer");
+        StructuredRequestLog.TimingHandler inner = new StructuredRequestLog.TimingHandler("inner", new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                try
+                {
+                    Thread.sleep(10);
+                }
+                catch (InterruptedException x)
+                {
+                    throw new InterruptedIOException();
+                }
+            }
+        });
+        outer.setHandler(inner);
+        _server.setHandler(outer);
+        _server.start();
+
+        _connector.getResponse("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n");
+        String entry = _entries.poll(5, TimeUnit.SECONDS);
+
+        Matcher outerMatcher = Pattern.compile("\"handler\\.outer\\.us\":(\\d+)").matcher(entry);
+        Matcher innerMatcher = Pattern.compile("\"handler\\.inner\\.us\":(\\d+)").matcher(entry);
+        assertTrue(outerMatcher.find());
+        assertTrue(innerMatcher.find());
+        long innerTime = Long.parseLong(innerMatcher.group(1));
+        assertThat(innerTime, greaterThanOrEqualTo(TimeUnit.MILLISECONDS.toMicros(10)));
+        assertThat(Long.parseLong(outerMatcher.group(1)), greaterThanOrEqualTo(innerTime));
+        // HTTP/1.1 has no stream id.
+        assertThat(entry, not(containsString("\"stream.id\"")));
+    }
+
+    @Test
+    public void testFieldsCannotChangeWhileStarted() throws Exception
+    {
+        start(new RequestLogFormatter.Json());
+        StructuredRequestLog requestLog = (StructuredRequestLog)_server.getRequestLog();
+
+        assertThrows(IllegalStateException.class, () -> requestLog.addField("other", (request, response) -> null));
+        assertThrows(IllegalStateException.class, () -> requestLog.removeField("custom"));
+
+        _server.stop();
+        requestLog.addField("other", (request, response) -> "value");
+        _server.start();
+
+        _connector.getResponse("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n");
+        assertThat(_entries.poll(5, TimeUnit.SECONDS), containsString("\"other\":\"value\""));
+    }
+
+    @Test
+    public void testCommonLogFormat()
+    {
+        Map<String, Object> fields = new LinkedHashMap<>();
+        fields.put(StructuredRequestLog.TIMESTAMP, Instant.ofEpochSecond(0));
+        fields.put(StructuredRequestLog.CLIENT_ADDRESS, "1.2.3.4");
+        fields.put(StructuredRequestLog.METHOD, "GET");
+        fields.put(StructuredRequestLog.URI, "/");
+        fields.put(StructuredRequestLog.PROTOCOL, "HTTP/1.1");
+        fields.put(StructuredRequestLog.STATUS, 200);
+        fields.put(StructuredRequestLog.BYTES_SENT, 0L);
+
+        String entry = new RequestLogFormatter.CommonLogFormat().format(fields);
+
+        assertEquals("1.2.3.4 - - [01/Jan/1970:00:00:00 +0000] \"GET / HTTP/1.1\" 200 -", entry);
+    }
+}
@@ -0,0 +1,159 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.atomic.AtomicLong;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+public class AdaptiveLoadShedHandlerTest
+{
+    private final AtomicLong _delay = new AtomicLong();
+    private Server _server;
+    private LocalConnector _connector;
+    private AdaptiveLoadShedHandler _loadShed;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        // Simulates the queue latency of an overloaded server.
+        HandlerWrapper delay = new HandlerWrapper()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+            {
+                try
+                {
+                    Thread.sleep(_delay.get());
+                }
+                catch (InterruptedException x)
+                {
+                    throw new ServletException(x);
+                }
+                super.handle(target, baseRequest, request, response);
+            }
+        };
+        _loadShed = new AdaptiveLoadShedHandler();
+        _loadShed.setEvaluationPeriod(0);
+        _loadShed.setMaxQueueLatency(50);
+        _loadShed.setIncreaseStep(0.5);
+        _loadShed.setDecreaseStep(0.5);
+        _loadShed.setMaxShedRatio(0.5);
+        _loadShed.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+            }
+        });
+        delay.setHandler(_loadShed);
+        _server.setHandler(delay);
+        _server.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path, String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder("GET ").append(path).append(" HTTP/1.1\r\nHost: localhost\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(_connector.getResponse(request.toString()));
+    }
+
+    @Test
+    public void testShedAndRecover() throws Exception
+    {
+        assertThat(get("/").getStatus(), is(HttpStatus.OK_200));
+        assertThat(_loadShed.getShedRatio(), is(0.0));
+
+        _delay.set(200);
+        HttpTester.Response response = get("/");
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(response.get(HttpHeader.RETRY_AFTER), is("1"));
+        assertThat(_loadShed.getShedRatio(), is(0.5));
+        assertThat(_loadShed.getQueueLatency() >= 50, is(true));
+
+        // Health checks are never shed.
+        assertThat(get("/readyz").getStatus(), is(HttpStatus.OK_200));
+        // Authenticated requests are shed last.
+        assertThat(get("/", "Authorization: Bearer token").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("/").getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(_loadShed.getShed(), is(2L));
+
+        // The server recovers when the load decreases.
+        _delay.set(0);
+        assertThat(get("/").getStatus(), is(HttpStatus.OK_200));
+        assertThat(_loadShed.getShedRatio(), is(0.0));
+    }
+
+    @Test
+    public void testCustomPriority() throws Exception
+    {
+        _server.stop();
+        AdaptiveLoadShedHandler loadShed = new AdaptiveLoadShedHandler()
+        {
+            @Override
+            protected Priority getPriority(String target, Request baseRequest)
+            {
+                if (target.startsWith("/admin/"))
+                    return Priority.CRITICAL;
+                return super.getPriority(target, baseRequest);
+            }
+        };
+        loadShed.setEvaluationPeriod(0);
+        loadShed.setMaxQueueLatency(50);
+        loadShed.setIncreaseStep(1);
+        loadShed.setMaxShedRatio(1);
+        loadShed.setRetryAfter(-1);
+        loadShed.setHandler(_loadShed.getHandler());
+        ((HandlerWrapper)_server.getHandler()).setHandler(loadShed);
+        _server.start();
+
+        _delay.set(200);
+        HttpTester.Response response = get("/", "Authorization: Bearer token");
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(response.get(HttpHeader.RETRY_AFTER), nullValue());
+        assertThat(get("/admin/status").getStatus(), is(HttpStatus.OK_200));
+        assertThat(loadShed.getAccepted(), is(1L));
+    }
+}
@@ -0,0 +1,125 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.Arrays;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.NanoTime;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+
+public class BandwidthLimitHandlerTest
+{
+    private static final int CONTENT_LENGTH = 4096;
+
+    private Server _server;
+    private LocalConnector _connector;
+    private BandwidthLimitHandler _bandwidthLimitHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _bandwidthLimitHandler = new BandwidthLimitHandler();
+        _bandwidthLimitHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                byte[] content = new byte[CONTENT_LENGTH];
+                Arrays.fill(content, (byte)'x');
+                response.setContentLength(content.length);
+                response.getOutputStream().write(content);
+            }
+        });
+        _server.setHandler(_bandwidthLimitHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String headers) throws Exception
+    {
+        String request = "GET / HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testUnlimited() throws Exception
+    {
+        _server.start();
+
+        long begin = NanoTime.now();
+        HttpTester.Response response = get("");
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes().length, is(CONTENT_LENGTH));
+        assertThat(NanoTime.millisSince(begin), lessThan(1000L));
+    }
+
+    @Test
+    public void testWriteRateLimitsResponseContent() throws Exception
+    {
+        // 1024 bytes in the burst, then 3072 bytes at 4096 bytes/s.
+        _bandwidthLimitHandler.setWriteRate(CONTENT_LENGTH);
+        _bandwidthLimitHandler.setBurst(1024);
+        _server.start();
+
+        long begin = NanoTime.now();
+        HttpTester.Response response = get("");
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes().length, is(CONTENT_LENGTH));
+        assertThat(NanoTime.millisSince(begin), greaterThanOrEqualTo(500L));
+    }
+
+    @Test
+    public void testKeyedLimiterIsShared() throws Exception
+    {
+        _bandwidthLimitHandler.setWriteRate(CONTENT_LENGTH);
+        _bandwidthLimitHandler.setBurst(CONTENT_LENGTH);
+        _bandwidthLimitHandler.setKeyExtractor(RateLimitHandler.header("X-Tenant"));
+        _server.start();
+
+        // The first request consumes the whole burst of the tenant.
+        assertThat(get("X-Tenant: one\r\n").getStatus(), is(HttpStatus.OK_200));
+
+        // The second request of the same tenant must wait for the limiter to refill.
+        long begin = NanoTime.now();
+        HttpTester.Response response = get("X-Tenant: one\r\n");
+        assertThat(response.getContentBytes().length, is(CONTENT_LENGTH));
+        assertThat(NanoTime.millisSince(begin), greaterThanOrEqualTo(500L));
+        assertThat(_bandwidthLimitHandler.getKeys(), is(1));
+    }
+}
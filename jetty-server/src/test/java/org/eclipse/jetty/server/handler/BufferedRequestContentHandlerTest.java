@@ -0,0 +1,205 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import java.util.stream.Stream;
+import javax.servlet.DispatcherType;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class BufferedRequestContentHandlerTest
+{
+    public WorkDir workDir;
+    private final AtomicReference<BufferedRequestContentHandler.BufferedContent> _content = new AtomicReference<>();
+    private Server _server;
+    private LocalConnector _connector;
+    private BufferedRequestContentHandler _bufferedHandler;
+    private Path _tempDir;
+
+    @BeforeEach
+    public void before()
+    {
+        _tempDir = workDir.getEmptyPathDir();
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _bufferedHandler = new BufferedRequestContentHandler();
+        _bufferedHandler.setTempDir(_tempDir);
+        _bufferedHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                _content.set(BufferedRequestContentHandler.getBufferedContent(request));
+                response.setHeader("X-Dispatcher-Type", request.getDispatcherType().name());
+                if (request.getParameter("name") != null)
+                {
+                    response.getWriter().print(request.getParameter("name"));
+                    return;
+                }
+                // Read the content twice, as a stream and as a reader.
+                String first = IO.toString(request.getInputStream(), StandardCharsets.UTF_8);
+                String second = IO.toString(request.getReader());
+                response.setHeader("X-Same", String.valueOf(first.equals(second)));
+                response.getWriter().print(first);
+            }
+        });
+        _server.setHandler(_bufferedHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response post(String headers, String content) throws Exception
+    {
+        String request = "POST / HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n" + content;
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    private static String chunked(String content)
+    {
+        return Integer.toHexString(content.length()) + "\r\n" + content + "\r\n0\r\n\r\n";
+    }
+
+    private long awaitTempFiles() throws Exception
+    {
+        // The temporary files are deleted asynchronously when the request completes.
+        long deadline = NanoTime.now() + TimeUnit.SECONDS.toNanos(5);
+        while (true)
+        {
+            try (Stream<Path> files = Files.list(_tempDir))
+            {
+                long count = files.count();
+                if (count == 0 || NanoTime.isBefore(deadline, NanoTime.now()))
+                    return count;
+            }
+            Thread.sleep(10);
+        }
+    }
+
+    @Test
+    public void testContentInMemoryCanBeReadMultipleTimes() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = post("Content-Length: 5\r\nContent-Type: text/plain;charset=UTF-8\r\n", "hello");
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello"));
+        assertThat(response.get("X-Same"), is("true"));
+        assertThat(response.get("X-Dispatcher-Type"), is(DispatcherType.REQUEST.name()));
+        assertThat(_content.get().isInMemory(), is(true));
+        assertThat(_content.get().getLength(), is(5L));
+    }
+
+    @Test
+    public void testLargeContentSpooledToFile() throws Exception
+    {
+        _bufferedHandler.setMaxMemorySize(16);
+        _server.start();
+
+        String content = "0123456789".repeat(100);
+        HttpTester.Response response = post("Transfer-Encoding: chunked\r\nContent-Type: text/plain;charset=UTF-8\r\n", chunked(content));
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(content));
+        assertThat(response.get("X-Same"), is("true"));
+        BufferedRequestContentHandler.BufferedContent buffered = _content.get();
+        assertThat(buffered.isInMemory(), is(false));
+        assertThat(buffered.getPath(), notNullValue());
+        assertThat(buffered.getLength(), is((long)content.length()));
+
+        assertThat(awaitTempFiles(), is(0L));
+    }
+
+    @Test
+    public void testRequestWithoutContentIsNotBuffered() throws Exception
+    {
+        _server.start();
+
+        String request = "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(request));
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(_content.get(), nullValue());
+    }
+
+    @Test
+    public void testContentLengthTooLarge() throws Exception
+    {
+        _bufferedHandler.setMaxContentSize(4);
+        _server.start();
+
+        HttpTester.Response response = post("Content-Length: 5\r\n", "hello");
+
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertThat(_content.get(), nullValue());
+    }
+
+    @Test
+    public void testChunkedContentTooLarge() throws Exception
+    {
+        _bufferedHandler.setMaxMemorySize(4);
+        _bufferedHandler.setMaxContentSize(8);
+        _server.start();
+
+        HttpTester.Response response = post("Transfer-Encoding: chunked\r\n", chunked("0123456789"));
+
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertThat(_content.get(), nullValue());
+        assertThat(awaitTempFiles(), is(0L));
+    }
+
+    @Test
+    public void testFormParameters() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = post("Content-Length: 10\r\nContent-Type: application/x-www-form-urlencoded\r\n", "name=jetty");
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("jetty"));
+        assertThat(_content.get().getLength(), is(10L));
+    }
+}
@@ -0,0 +1,151 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class BulkheadHandlerTest
+{
+    private final CountDownLatch _blocked = new CountDownLatch(1);
+    private Server _server;
+    private LocalConnector _connector;
+    private BulkheadHandler _bulkhead;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _bulkhead = new BulkheadHandler();
+        _bulkhead.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws InterruptedException
+            {
+                baseRequest.setHandled(true);
+                if (target.endsWith("/slow"))
+                    _blocked.await(10, TimeUnit.SECONDS);
+                response.setStatus(HttpStatus.OK_200);
+            }
+        });
+        _server.setHandler(_bulkhead);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _blocked.countDown();
+        _server.stop();
+    }
+
+    private static String request(String host, String path)
+    {
+        return "GET " + path + " HTTP/1.1\r\nHost: " + host + "\r\nConnection: close\r\n\r\n";
+    }
+
+    @Test
+    public void testPartitionIsolation() throws Exception
+    {
+        BulkheadHandler.Partition partition = _bulkhead.addPartition("a", 1, 1);
+        partition.addVirtualHost("a.example.com");
+        _server.start();
+
+        LocalConnector.LocalEndPoint first = _connector.executeRequest(request("a.example.com", "/slow"));
+        await().atMost(5, TimeUnit.SECONDS).until(partition::getActive, is(1));
+
+        // The second request is queued.
+        LocalConnector.LocalEndPoint second = _connector.executeRequest(request("a.example.com", "/fast"));
+        await().atMost(5, TimeUnit.SECONDS).until(partition::getQueueSize, is(1));
+
+        // The third request is rejected.
+        HttpTester.Response third = HttpTester.parseResponse(_connector.getResponse(request("a.example.com", "/fast")));
+        assertThat(third.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(third.get(HttpHeader.RETRY_AFTER), is("1"));
+
+        // Other virtual hosts are not affected.
+        HttpTester.Response other = HttpTester.parseResponse(_connector.getResponse(request("b.example.com", "/fast")));
+        assertThat(other.getStatus(), is(HttpStatus.OK_200));
+
+        _blocked.countDown();
+        assertThat(HttpTester.parseResponse(first.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(second.getResponse()).getStatus(), is(HttpStatus.OK_200));
+
+        await().atMost(5, TimeUnit.SECONDS).until(partition::getActive, is(0));
+        assertThat(partition.getAccepted(), is(2L));
+        assertThat(partition.getRejected(), is(1L));
+        assertThat(partition.getMaxActive(), is(1));
+        assertThat(_bulkhead.getDefaultPartition().getAccepted(), is(1L));
+    }
+
+    @Test
+    public void testQueueTimeout() throws Exception
+    {
+        BulkheadHandler.Partition partition = _bulkhead.addPartition("app", 1, 10);
+        partition.addContextPath("/app");
+        partition.setQueueTimeout(500);
+        _server.start();
+
+        LocalConnector.LocalEndPoint first = _connector.executeRequest(request("localhost", "/app/slow"));
+        await().atMost(5, TimeUnit.SECONDS).until(partition::getActive, is(1));
+        assertThat(partition.getSaturation(), is(1.0));
+
+        HttpTester.Response second = HttpTester.parseResponse(_connector.getResponse(request("localhost", "/app/fast"), 5, TimeUnit.SECONDS));
+        assertThat(second.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(partition.getTimedOut(), is(1L));
+        assertThat(partition.getQueueSize(), is(0));
+
+        _blocked.countDown();
+        assertThat(HttpTester.parseResponse(first.getResponse()).getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testLongestContextPathMatch() throws Exception
+    {
+        BulkheadHandler.Partition app = _bulkhead.addPartition("app", 10, 0);
+        app.addContextPath("/app");
+        BulkheadHandler.Partition admin = _bulkhead.addPartition("admin", 10, 0);
+        admin.addContextPath("/app/admin");
+        BulkheadHandler.Partition host = _bulkhead.addPartition("host", 10, 0);
+        host.addVirtualHost("*.example.com");
+        _server.start();
+
+        _connector.getResponse(request("localhost", "/app/fast"));
+        _connector.getResponse(request("localhost", "/app/admin/fast"));
+        _connector.getResponse(request("localhost", "/application"));
+        _connector.getResponse(request("www.example.com", "/other"));
+
+        assertThat(app.getAccepted(), is(1L));
+        assertThat(admin.getAccepted(), is(1L));
+        assertThat(host.getAccepted(), is(1L));
+        assertThat(_bulkhead.getDefaultPartition().getAccepted(), is(1L));
+    }
+}
@@ -0,0 +1,151 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.util.List;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.ContentNegotiationHandler.Representation;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.hamcrest.Matchers.startsWith;
+
+public class ContentNegotiationHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private ContentNegotiationHandler negotiationHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        negotiationHandler = new ContentNegotiationHandler();
+        server.setHandler(negotiationHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private Representation representation(String mediaType, String charset, String language, String content)
+    {
+        Representation representation = new Representation(mediaType, (r, baseRequest, request, response) -> response.getWriter().print(content));
+        representation.setCharset(charset);
+        representation.setLanguage(language);
+        return representation;
+    }
+
+    private HttpTester.Response get(String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder("GET / HTTP/1.1\r\nHost: localhost\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(connector.getResponse(request.toString()));
+    }
+
+    @Test
+    public void testMediaTypeNegotiation() throws Exception
+    {
+        negotiationHandler.addRepresentation(representation("text/html", "utf-8", null, "html"));
+        negotiationHandler.addRepresentation(representation("application/json", "utf-8", null, "json"));
+        server.start();
+
+        HttpTester.Response response = get();
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("html"));
+        assertThat(response.get(HttpHeader.VARY), is("Accept"));
+
+        response = get("Accept: text/html;q=0.5, application/json");
+        assertThat(response.getContent(), is("json"));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), startsWith("application/json"));
+
+        // The most specific range wins: text/html is excluded despite text/*.
+        response = get("Accept: text/*, text/html;q=0, */*;q=0.1");
+        assertThat(response.getContent(), is("json"));
+    }
+
+    @Test
+    public void testLanguageAndCharsetNegotiation() throws Exception
+    {
+        negotiationHandler.addRepresentation(representation("text/plain", "utf-8", "en", "hello"));
+        negotiationHandler.addRepresentation(representation("text/plain", "iso-8859-1", "fr-CA", "bonjour"));
+        server.start();
+
+        HttpTester.Response response = get("Accept-Language: fr;q=0.9, en;q=0.8");
+        assertThat(response.getContent(), is("bonjour"));
+        assertThat(response.get(HttpHeader.CONTENT_LANGUAGE), is("fr-CA"));
+        assertThat(response.get(HttpHeader.VARY), is("Accept-Charset, Accept-Language"));
+
+        response = get("Accept-Language: fr;q=0.9, en;q=0.8", "Accept-Charset: utf-8");
+        assertThat(response.getContent(), is("hello"));
+        assertThat(response.get(HttpHeader.CONTENT_LANGUAGE), is("en"));
+    }
+
+    @Test
+    public void testNotAcceptable() throws Exception
+    {
+        negotiationHandler.addRepresentation(representation("text/html", null, "en", "html"));
+        negotiationHandler.addRepresentation(representation("application/json", null, "en", "json"));
+        server.start();
+
+        HttpTester.Response response = get("Accept: image/png");
+        assertThat(response.getStatus(), is(HttpStatus.NOT_ACCEPTABLE_406));
+        assertThat(response.get(HttpHeader.VARY), is("Accept"));
+        assertThat(negotiationHandler.getNotAcceptable(), is(1L));
+
+        negotiationHandler.setFallbackToDefault(true);
+        response = get("Accept: image/png");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("html"));
+        assertThat(negotiationHandler.getNotAcceptable(), is(1L));
+    }
+
+    @Test
+    public void testEncodingNegotiation()
+    {
+        Representation identity = representation("text/plain", null, null, "plain");
+        Representation gzip = representation("text/plain", null, null, "gzipped");
+        gzip.setEncoding("gzip");
+        List<Representation> representations = List.of(gzip, identity);
+
+        assertThat(ContentNegotiationHandler.getVary(representations), is("Accept-Encoding"));
+        assertThat(ContentNegotiationHandler.negotiate(HttpFields.build(), representations), sameInstance(gzip));
+        assertThat(ContentNegotiationHandler.negotiate(HttpFields.build().add(HttpHeader.ACCEPT_ENCODING, "br"), representations), sameInstance(identity));
+        assertThat(ContentNegotiationHandler.negotiate(HttpFields.build().add(HttpHeader.ACCEPT_ENCODING, ""), representations), sameInstance(identity));
+        assertThat(ContentNegotiationHandler.negotiate(HttpFields.build().add(HttpHeader.ACCEPT_ENCODING, "*;q=0"), representations), nullValue());
+
+        // The source quality degrades the compressed representation.
+        gzip.setQuality(0.5D);
+        assertThat(ContentNegotiationHandler.negotiate(HttpFields.build().add(HttpHeader.ACCEPT_ENCODING, "gzip, identity;q=0.8"), representations), sameInstance(identity));
+    }
+}
@@ -0,0 +1,193 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.charset.StandardCharsets;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.TypeUtil;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+public class ContentTypeEnforcementHandlerTest
+{
+    private static final byte[] PNG = TypeUtil.fromHexString("89504E470D0A1A0A0000000D49484452");
+    private static final byte[] HTML = "<html><body>not an image</body></html>".getBytes(StandardCharsets.UTF_8);
+    private static final byte[] LATIN1 = "café".getBytes(StandardCharsets.ISO_8859_1);
+
+    private Server _server;
+    private LocalConnector _connector;
+    private ContentTypeEnforcementHandler _enforcementHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _enforcementHandler = new ContentTypeEnforcementHandler();
+        _enforcementHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType(request.getParameter("type"));
+                byte[] content;
+                switch (request.getParameter("content"))
+                {
+                    case "png":
+                        content = PNG;
+                        break;
+                    case "html":
+                        content = HTML;
+                        break;
+                    case "latin1":
+                        content = LATIN1;
+                        break;
+                    default:
+                        throw new IllegalArgumentException();
+                }
+
+                OutputStream output = response.getOutputStream();
+                if (request.getParameter("bytewise") == null)
+                {
+                    output.write(content);
+                }
+                else
+                {
+                    // Write and flush one byte at a time.
+                    for (byte b : content)
+                    {
+                        output.write(b);
+                        response.flushBuffer();
+                    }
+                }
+            }
+        });
+        _server.setHandler(_enforcementHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String query) throws Exception
+    {
+        String request = "GET /?" + query + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testValidContent() throws Exception
+    {
+        _enforcementHandler.setMode(ContentTypeEnforcementHandler.Mode.REJECT);
+        _server.start();
+
+        HttpTester.Response response = get("type=image/png&content=png");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(ContentTypeEnforcementHandler.X_CONTENT_TYPE_OPTIONS), is("nosniff"));
+        assertThat(response.getContentBytes(), is(PNG));
+
+        response = get("type=text/html;charset=utf-8&content=html");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes(), is(HTML));
+
+        // Content in another charset is not checked.
+        response = get("type=text/plain;charset=iso-8859-1&content=latin1");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(ContentTypeEnforcementHandler.X_CONTENT_TYPE_OPTIONS), nullValue());
+        assertThat(response.getContentBytes(), is(LATIN1));
+
+        assertThat(_enforcementHandler.getChecked(), is(2L));
+        assertThat(_enforcementHandler.getViolations(), is(0L));
+    }
+
+    @Test
+    public void testViolationLogged() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = get("type=image/png&content=html");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), is("image/png"));
+        assertThat(response.getContentBytes(), is(HTML));
+
+        assertThat(_enforcementHandler.getViolations(), is(1L));
+        assertThat(_enforcementHandler.getRejected(), is(0L));
+    }
+
+    @Test
+    public void testViolationRejected() throws Exception
+    {
+        _enforcementHandler.setMode(ContentTypeEnforcementHandler.Mode.REJECT);
+        _server.start();
+
+        HttpTester.Response response = get("type=image/gif&content=png");
+        assertThat(response.getStatus(), is(HttpStatus.INTERNAL_SERVER_ERROR_500));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), nullValue());
+        assertThat(response.get(ContentTypeEnforcementHandler.X_CONTENT_TYPE_OPTIONS), is("nosniff"));
+        assertThat(response.get(HttpHeader.CONTENT_LENGTH), is("0"));
+
+        response = get("type=application/json&content=latin1");
+        assertThat(response.getStatus(), is(HttpStatus.INTERNAL_SERVER_ERROR_500));
+
+        assertThat(_enforcementHandler.getViolations(), is(2L));
+        assertThat(_enforcementHandler.getRejected(), is(2L));
+    }
+
+    @Test
+    public void testHeadHeldBackUntilValidated() throws Exception
+    {
+        _enforcementHandler.setMode(ContentTypeEnforcementHandler.Mode.REJECT);
+        _server.start();
+
+        HttpTester.Response response = get("type=image/png&content=png&bytewise=true");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes(), is(PNG));
+
+        // The flushes do not commit the response before the magic bytes are checked.
+        response = get("type=image/png&content=html&bytewise=true");
+        assertThat(response.getStatus(), is(HttpStatus.INTERNAL_SERVER_ERROR_500));
+        assertThat(response.get(HttpHeader.CONTENT_LENGTH), is("0"));
+    }
+
+    @Test
+    public void testCustomValidator() throws Exception
+    {
+        _enforcementHandler.setMode(ContentTypeEnforcementHandler.Mode.REJECT);
+        _enforcementHandler.putValidator("application/pdf", ContentTypeEnforcementHandler.MagicValidator.of("25504446"));
+        _enforcementHandler.removeValidator("image/png");
+        _server.start();
+
+        assertThat(get("type=application/pdf&content=html").getStatus(), is(HttpStatus.INTERNAL_SERVER_ERROR_500));
+        assertThat(get("type=image/png&content=html").getStatus(), is(HttpStatus.OK_200));
+    }
+}
@@ -0,0 +1,203 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.atomic.AtomicBoolean;
+import javax.servlet.ServletOutputStream;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class DeferredCommitHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private DeferredCommitHandler _deferredHandler;
+    private TestHandler _handler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _deferredHandler = new DeferredCommitHandler();
+        _deferredHandler.setCommitWindow(64);
+        _deferredHandler.setHandler(_handler = new TestHandler());
+        _server.setHandler(_deferredHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get() throws Exception
+    {
+        return HttpTester.parseResponse(_connector.getResponse("GET /path HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"));
+    }
+
+    @Test
+    public void testHeadersChangedAfterWrite() throws Exception
+    {
+        AtomicBoolean committed = new AtomicBoolean(true);
+        _handler._action = (request, response) ->
+        {
+            ServletOutputStream output = response.getOutputStream();
+            output.write("hello".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+            committed.set(response.isCommitted());
+            response.setStatus(HttpStatus.CREATED_201);
+            response.setHeader("X-Late", "true");
+        };
+        _server.start();
+
+        HttpTester.Response response = get();
+        assertThat(committed.get(), is(false));
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        assertThat(response.get("X-Late"), is("true"));
+        assertThat(response.get(HttpHeader.CONTENT_LENGTH), is("5"));
+        assertThat(response.getContent(), is("hello"));
+    }
+
+    @Test
+    public void testWindowExceeded() throws Exception
+    {
+        AtomicBoolean committed = new AtomicBoolean();
+        _handler._action = (request, response) ->
+        {
+            ServletOutputStream output = response.getOutputStream();
+            output.write(new byte[32]);
+            output.flush();
+            assertThat(response.isCommitted(), is(false));
+            output.write(new byte[64]);
+            output.flush();
+            committed.set(response.isCommitted());
+            response.setHeader("X-Late", "true");
+        };
+        _server.start();
+
+        HttpTester.Response response = get();
+        assertThat(committed.get(), is(true));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Late"), nullValue());
+        assertThat(response.getContentBytes().length, is(96));
+    }
+
+    @Test
+    public void testFailureAfterWriteMapsToError() throws Exception
+    {
+        _handler._action = (request, response) ->
+        {
+            ServletOutputStream output = response.getOutputStream();
+            output.write("partial".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+            response.sendError(HttpStatus.SERVICE_UNAVAILABLE_503);
+        };
+        _server.start();
+
+        HttpTester.Response response = get();
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(response.getContent(), not(is("partial")));
+    }
+
+    @Test
+    public void testExplicitFlushCommits() throws Exception
+    {
+        AtomicBoolean committed = new AtomicBoolean();
+        _handler._action = (request, response) ->
+        {
+            response.getOutputStream().write("hello".getBytes(StandardCharsets.UTF_8));
+            DeferredCommitHandler.flush(request);
+            committed.set(response.isCommitted());
+        };
+        _server.start();
+
+        HttpTester.Response response = get();
+        assertThat(committed.get(), is(true));
+        assertThat(response.getContent(), is("hello"));
+    }
+
+    @Test
+    public void testETagAndListener() throws Exception
+    {
+        _deferredHandler.setETagGenerated(true);
+        _deferredHandler.addListener((request, response, content, complete) ->
+            response.setHeader("X-Held", content.remaining() + "/" + complete));
+        _handler._action = (request, response) -> response.getOutputStream().write("hello".getBytes(StandardCharsets.UTF_8));
+        _server.start();
+
+        HttpTester.Response response1 = get();
+        String etag = response1.get(HttpHeader.ETAG);
+        assertThat(etag, notNullValue());
+        assertThat(response1.get("X-Held"), is("5/true"));
+
+        HttpTester.Response response2 = get();
+        assertThat(response2.get(HttpHeader.ETAG), is(etag));
+    }
+
+    @Test
+    public void testExcludedMimeTypeIsNotDeferred() throws Exception
+    {
+        AtomicBoolean committed = new AtomicBoolean();
+        _handler._action = (request, response) ->
+        {
+            response.setContentType("text/event-stream");
+            ServletOutputStream output = response.getOutputStream();
+            output.write("data: 1\n\n".getBytes(StandardCharsets.UTF_8));
+            output.flush();
+            committed.set(response.isCommitted());
+        };
+        _server.start();
+
+        get();
+        assertThat(committed.get(), is(true));
+    }
+
+    private static class TestHandler extends AbstractHandler
+    {
+        private Action _action;
+
+        @Override
+        public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            baseRequest.setHandled(true);
+            _action.run(request, response);
+        }
+    }
+
+    @FunctionalInterface
+    private interface Action
+    {
+        void run(HttpServletRequest request, HttpServletResponse response) throws IOException;
+    }
+}
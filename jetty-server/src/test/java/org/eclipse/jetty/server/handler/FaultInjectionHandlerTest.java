@@ -0,0 +1,192 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class FaultInjectionHandlerTest
+{
+    private static final String CONTENT = "0123456789".repeat(10);
+
+    private Server _server;
+    private LocalConnector _connector;
+    private FaultInjectionHandler _faultHandler;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _faultHandler = new FaultInjectionHandler();
+        _faultHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                response.setHeader("X-Dispatcher-Type", request.getDispatcherType().name());
+                if (request.getParameter("chunked") == null)
+                    response.setContentLength(CONTENT.length());
+                response.getWriter().print(CONTENT);
+            }
+        });
+        _server.setHandler(_faultHandler);
+        _server.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private String get(String uri, String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder("GET " + uri + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        LocalConnector.LocalEndPoint endPoint = _connector.executeRequest(request.append("\r\n").toString());
+        endPoint.waitUntilClosed();
+        return endPoint.takeOutputString();
+    }
+
+    @Test
+    public void testRulesRoundTrip()
+    {
+        _faultHandler.setRules("latency;path=/api/*;probability=0.5;latency=200, error;header=X-Chaos:on;status=502");
+        assertThat(_faultHandler.getRules(), is("LATENCY;path=/api/*;probability=0.5;latency=200,ERROR;header=X-Chaos:on;status=502"));
+
+        _faultHandler.addRule("latency;latency=100;blocking=true");
+        assertThat(_faultHandler.getRules(), endsWith(",LATENCY;latency=100;blocking=true"));
+
+        _faultHandler.addRule("corrupt-chunked;bytes=10");
+        assertThat(_faultHandler.getRules(), endsWith(",CORRUPT_CHUNKED;bytes=10"));
+
+        _faultHandler.clearRules();
+        assertThat(_faultHandler.getRules(), is(""));
+
+        assertThrows(IllegalArgumentException.class, () -> _faultHandler.addRule("unknown"));
+        assertThrows(IllegalArgumentException.class, () -> _faultHandler.addRule("error;status=200"));
+        assertThrows(IllegalArgumentException.class, () -> _faultHandler.addRule("error;probability=2"));
+    }
+
+    @Test
+    public void testErrorScopedByPathAndHeader() throws Exception
+    {
+        _faultHandler.setRules("error;path=/api/*;header=X-Chaos:on;status=503");
+
+        assertThat(HttpTester.parseResponse(get("/api/x", "X-Chaos: on")).getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(HttpTester.parseResponse(get("/api/x", "X-Chaos: off")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(get("/api/x")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(get("/other", "X-Chaos: on")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(_faultHandler.getInjected(), is(1L));
+
+        _faultHandler.setEnabled(false);
+        assertThat(HttpTester.parseResponse(get("/api/x", "X-Chaos: on")).getStatus(), is(HttpStatus.OK_200));
+        assertThat(_faultHandler.getInjected(), is(1L));
+    }
+
+    @Test
+    public void testProbability() throws Exception
+    {
+        _faultHandler.setRules("error;probability=0");
+        for (int i = 0; i < 10; ++i)
+        {
+            assertThat(HttpTester.parseResponse(get("/")).getStatus(), is(HttpStatus.OK_200));
+        }
+        assertThat(_faultHandler.getInjected(), is(0L));
+    }
+
+    @Test
+    public void testLatency() throws Exception
+    {
+        _faultHandler.setRules("latency;latency=300");
+
+        long begin = System.nanoTime();
+        HttpTester.Response response = HttpTester.parseResponse(get("/"));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin), greaterThanOrEqualTo(300L));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Dispatcher-Type"), is("REQUEST"));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testBlockingLatency() throws Exception
+    {
+        _faultHandler.setRules("latency;latency=300;blocking=true");
+
+        long begin = System.nanoTime();
+        HttpTester.Response response = HttpTester.parseResponse(get("/"));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - begin), greaterThanOrEqualTo(300L));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testAbort() throws Exception
+    {
+        _faultHandler.setRules("abort");
+        assertThat(get("/"), is(""));
+    }
+
+    @Test
+    public void testTruncate() throws Exception
+    {
+        _faultHandler.setRules("truncate;bytes=10");
+
+        String response = get("/");
+        assertThat(response, containsString("Content-Length: " + CONTENT.length()));
+        assertThat(response, endsWith("\r\n\r\n0123456789"));
+    }
+
+    @Test
+    public void testCorruptChunked() throws Exception
+    {
+        _faultHandler.setRules("corrupt_chunked;bytes=5");
+
+        String response = get("/?chunked=true");
+        assertThat(response, containsString("HTTP/1.1 200 OK"));
+        assertThat(response, containsString("Transfer-Encoding: chunked"));
+        assertThat(response, not(containsString("Content-Length")));
+        assertThat(response, endsWith("\r\n\r\n5\r\n01234\r\nZZ\r\n"));
+
+        // HTTP/1.0 requests are not corrupted.
+        String http10 = _connector.getResponse("GET /?chunked=true HTTP/1.0\r\n\r\n");
+        assertThat(HttpTester.parseResponse(http10).getContent(), is(CONTENT));
+    }
+}
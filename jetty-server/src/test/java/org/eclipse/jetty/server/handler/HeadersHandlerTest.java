@@ -0,0 +1,157 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class HeadersHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private HeadersHandler _headersHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _headersHandler = new HeadersHandler();
+        _headersHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if (target.startsWith("/missing"))
+                    response.setStatus(HttpStatus.NOT_FOUND_404);
+                if (target.startsWith("/app"))
+                {
+                    response.setHeader("X-Content-Type-Options", "custom");
+                    response.setHeader("X-Powered-By", "app");
+                }
+                if (target.endsWith(".json"))
+                    response.setContentType("application/json");
+                else
+                    response.setContentType("text/html;charset=utf-8");
+                Object nonce = request.getAttribute(HeadersHandler.CSP_NONCE_ATTRIBUTE);
+                response.getOutputStream().write(("nonce=" + nonce).getBytes(StandardCharsets.UTF_8));
+            }
+        });
+        _server.setHandler(_headersHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testSecurityDefaults() throws Exception
+    {
+        _headersHandler.setSecurityDefaults();
+        _server.start();
+
+        HttpTester.Response response = get("/index.html");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("X-Content-Type-Options"), is("nosniff"));
+        assertThat(response.get("Referrer-Policy"), is("strict-origin-when-cross-origin"));
+        assertThat(response.get("Cross-Origin-Opener-Policy"), is("same-origin"));
+        // HSTS is only sent over secure connections.
+        assertThat(response.get("Strict-Transport-Security"), nullValue());
+        assertThat(response.get("Content-Security-Policy"), nullValue());
+
+        // Presets do not override the headers set by the application.
+        response = get("/app");
+        assertThat(response.get("X-Content-Type-Options"), is("custom"));
+    }
+
+    @Test
+    public void testRuleConditions() throws Exception
+    {
+        _headersHandler.set("Cache-Control", "no-store").includePaths("/api/*");
+        _headersHandler.add("X-Error", "true").includeStatuses(HttpStatus.NOT_FOUND_404);
+        _headersHandler.add("X-Json", "true").includeMimeTypes("application/json");
+        _headersHandler.remove("X-Powered-By").excludePaths("/app/keep");
+        _server.start();
+
+        HttpTester.Response response = get("/api/data.json");
+        assertThat(response.get("Cache-Control"), is("no-store"));
+        assertThat(response.get("X-Json"), is("true"));
+        assertThat(response.get("X-Error"), nullValue());
+
+        response = get("/missing");
+        assertThat(response.getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(response.get("X-Error"), is("true"));
+        assertThat(response.get("Cache-Control"), nullValue());
+        assertThat(response.get("X-Json"), nullValue());
+
+        assertThat(get("/app").get("X-Powered-By"), nullValue());
+        assertThat(get("/app/keep").get("X-Powered-By"), is("app"));
+    }
+
+    @Test
+    public void testContentSecurityPolicyNonce() throws Exception
+    {
+        _headersHandler.setContentSecurityPolicy("script-src 'nonce-{nonce}'");
+        _server.start();
+
+        HttpTester.Response response1 = get("/index.html");
+        String csp1 = response1.get("Content-Security-Policy");
+        assertThat(csp1, notNullValue());
+        String nonce1 = csp1.substring("script-src 'nonce-".length(), csp1.length() - 1);
+        assertThat(response1.getContent(), is("nonce=" + nonce1));
+
+        HttpTester.Response response2 = get("/index.html");
+        String csp2 = response2.get("Content-Security-Policy");
+        assertThat(csp2, containsString("'nonce-"));
+        assertThat(csp2.equals(csp1), is(false));
+    }
+
+    @Test
+    public void testNoNonceWithoutPlaceholder() throws Exception
+    {
+        _headersHandler.setContentSecurityPolicy("default-src 'self'");
+        _server.start();
+
+        HttpTester.Response response = get("/index.html");
+        assertThat(response.get("Content-Security-Policy"), is("default-src 'self'"));
+        assertThat(response.getContent(), is("nonce=null"));
+    }
+}
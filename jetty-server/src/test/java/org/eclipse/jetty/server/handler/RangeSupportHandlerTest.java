@@ -0,0 +1,217 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.charset.StandardCharsets;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.DateGenerator;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.startsWith;
+
+public class RangeSupportHandlerTest
+{
+    private static final byte[] CONTENT = "0123456789abcdefghij".getBytes(StandardCharsets.US_ASCII);
+    private static final String ETAG = "\"v1\"";
+    private static final long LAST_MODIFIED = 1_700_000_000_000L;
+
+    private Server _server;
+    private LocalConnector _connector;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        RangeSupportHandler rangeSupportHandler = new RangeSupportHandler();
+        rangeSupportHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if ("/plain".equals(target))
+                {
+                    response.getWriter().print("plain");
+                    return;
+                }
+                RangeSupportHandler.setContentSource(request, new SeekableContentSource()
+                {
+                    @Override
+                    public long getLength()
+                    {
+                        return CONTENT.length;
+                    }
+
+                    @Override
+                    public String getContentType()
+                    {
+                        return "text/plain";
+                    }
+
+                    @Override
+                    public String getETag()
+                    {
+                        return ETAG;
+                    }
+
+                    @Override
+                    public long getLastModified()
+                    {
+                        return LAST_MODIFIED;
+                    }
+
+                    @Override
+                    public void writeTo(OutputStream output, long offset, long length) throws IOException
+                    {
+                        output.write(CONTENT, (int)offset, (int)length);
+                    }
+                });
+            }
+        });
+        _server.setHandler(rangeSupportHandler);
+        _server.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response request(String method, String path, String extraHeaders) throws Exception
+    {
+        String request = method + " " + path + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            extraHeaders +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testWholeContent() throws Exception
+    {
+        HttpTester.Response response = request("GET", "/", "");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.ACCEPT_RANGES), is("bytes"));
+        assertThat(response.get(HttpHeader.ETAG), is(ETAG));
+        assertThat(response.get(HttpHeader.LAST_MODIFIED), is(DateGenerator.formatDate(LAST_MODIFIED)));
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), startsWith("text/plain"));
+        assertThat(response.getContent(), is("0123456789abcdefghij"));
+    }
+
+    @Test
+    public void testSingleRange() throws Exception
+    {
+        HttpTester.Response response = request("GET", "/", "Range: bytes=2-5\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+        assertThat(response.get(HttpHeader.CONTENT_RANGE), is("bytes 2-5/20"));
+        assertThat(response.getContent(), is("2345"));
+
+        response = request("GET", "/", "Range: bytes=-3\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+        assertThat(response.get(HttpHeader.CONTENT_RANGE), is("bytes 17-19/20"));
+        assertThat(response.getContent(), is("hij"));
+    }
+
+    @Test
+    public void testMultipleRanges() throws Exception
+    {
+        HttpTester.Response response = request("GET", "/", "Range: bytes=0-1,10-12\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+        String contentType = response.get(HttpHeader.CONTENT_TYPE);
+        assertThat(contentType, startsWith("multipart/byteranges; boundary="));
+        String boundary = contentType.substring(contentType.indexOf('=') + 1);
+        String content = response.getContent();
+        // The declared Content-Length matches the actual content.
+        assertThat(response.getLongField(HttpHeader.CONTENT_LENGTH.asString()), is((long)content.length()));
+        assertThat(content, containsString("Content-Range: bytes 0-1/20\r\n\r\n01\r\n--" + boundary));
+        assertThat(content, containsString("Content-Range: bytes 10-12/20\r\n\r\nabc\r\n--" + boundary + "--"));
+        assertThat(content, containsString("Content-Type: text/plain"));
+    }
+
+    @Test
+    public void testUnsatisfiableRange() throws Exception
+    {
+        HttpTester.Response response = request("GET", "/", "Range: bytes=50-60\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.RANGE_NOT_SATISFIABLE_416));
+        assertThat(response.get(HttpHeader.CONTENT_RANGE), is("bytes */20"));
+    }
+
+    @Test
+    public void testIfRange() throws Exception
+    {
+        // Matching entity tag.
+        HttpTester.Response response = request("GET", "/", "Range: bytes=0-1\r\nIf-Range: " + ETAG + "\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+        assertThat(response.getContent(), is("01"));
+
+        // Changed entity tag.
+        response = request("GET", "/", "Range: bytes=0-1\r\nIf-Range: \"v0\"\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("0123456789abcdefghij"));
+
+        // Weak entity tags never match.
+        response = request("GET", "/", "Range: bytes=0-1\r\nIf-Range: W/" + ETAG + "\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        // Matching date.
+        response = request("GET", "/", "Range: bytes=0-1\r\nIf-Range: " + DateGenerator.formatDate(LAST_MODIFIED) + "\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+
+        // Older date.
+        response = request("GET", "/", "Range: bytes=0-1\r\nIf-Range: " + DateGenerator.formatDate(LAST_MODIFIED - 60_000) + "\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testRangeIgnoredForHead() throws Exception
+    {
+        String response = _connector.getResponse(
+            "HEAD / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Range: bytes=2-5\r\n" +
+                "Connection: close\r\n" +
+                "\r\n");
+        assertThat(response, containsString("HTTP/1.1 200 OK"));
+        assertThat(response, containsString("Content-Length: 20"));
+        assertThat(response, not(containsString("0123")));
+    }
+
+    @Test
+    public void testNoContentSource() throws Exception
+    {
+        HttpTester.Response response = request("GET", "/plain", "Range: bytes=0-1\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("plain"));
+    }
+}
@@ -0,0 +1,132 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class RateLimitHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private RateLimitHandler _rateLimitHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _rateLimitHandler = new RateLimitHandler();
+        _rateLimitHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+            }
+        });
+        _server.setHandler(_rateLimitHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String headers) throws Exception
+    {
+        String request = "GET / HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testTokenBucketRejectsExcess() throws Exception
+    {
+        _rateLimitHandler.setRate(0.1);
+        _rateLimitHandler.setBurst(2);
+        _server.start();
+
+        assertThat(get("").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("").getStatus(), is(HttpStatus.OK_200));
+        HttpTester.Response response = get("");
+        assertThat(response.getStatus(), is(HttpStatus.TOO_MANY_REQUESTS_429));
+        assertThat(response.get(HttpHeader.RETRY_AFTER), notNullValue());
+
+        assertThat(_rateLimitHandler.getRequestsAllowed(), is(2L));
+        assertThat(_rateLimitHandler.getRequestsRejected(), is(1L));
+    }
+
+    @Test
+    public void testHeaderKeyExtractor() throws Exception
+    {
+        _rateLimitHandler.setRate(0.1);
+        _rateLimitHandler.setBurst(1);
+        _rateLimitHandler.setKeyExtractor(RateLimitHandler.header("X-API-Key"));
+        _server.start();
+
+        assertThat(get("X-API-Key: one\r\n").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("X-API-Key: one\r\n").getStatus(), is(HttpStatus.TOO_MANY_REQUESTS_429));
+        assertThat(get("X-API-Key: two\r\n").getStatus(), is(HttpStatus.OK_200));
+        // Requests without key are not limited.
+        assertThat(get("").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("").getStatus(), is(HttpStatus.OK_200));
+        assertThat(_rateLimitHandler.getKeys(), is(2));
+    }
+
+    @Test
+    public void testLeakyBucketDelaysExcess() throws Exception
+    {
+        _rateLimitHandler.setAlgorithm(RateLimitHandler.Algorithm.LEAKY_BUCKET);
+        _rateLimitHandler.setRate(5);
+        _rateLimitHandler.setBurst(1);
+        _server.start();
+
+        String request = "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        LocalConnector.LocalEndPoint first = _connector.executeRequest(request);
+        LocalConnector.LocalEndPoint second = _connector.executeRequest(request);
+        LocalConnector.LocalEndPoint third = _connector.executeRequest(request);
+
+        int ok = 0;
+        int rejected = 0;
+        for (LocalConnector.LocalEndPoint endPoint : new LocalConnector.LocalEndPoint[]{first, second, third})
+        {
+            int status = HttpTester.parseResponse(endPoint.getResponse()).getStatus();
+            if (status == HttpStatus.OK_200)
+                ++ok;
+            else if (status == HttpStatus.TOO_MANY_REQUESTS_429)
+                ++rejected;
+        }
+
+        assertThat(ok, is(2));
+        assertThat(rejected, is(1));
+        assertThat(_rateLimitHandler.getRequestsDelayed(), is(1L));
+    }
+}
@@ -0,0 +1,188 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.AsyncContext;
+import javax.servlet.ServletOutputStream;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.hamcrest.Matchers.not;
+
+public class RequestDeadlineHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private RequestDeadlineHandler _deadlineHandler;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _deadlineHandler = new RequestDeadlineHandler();
+        _deadlineHandler.setDeadline(500);
+        _deadlineHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                switch (target)
+                {
+                    case "/sleep":
+                        sleep(2000);
+                        break;
+                    case "/async":
+                        AsyncContext asyncContext = request.startAsync();
+                        asyncContext.setTimeout(0);
+                        break;
+                    case "/stall":
+                    {
+                        ServletOutputStream output = response.getOutputStream();
+                        output.write("stalled".getBytes());
+                        output.flush();
+                        sleep(2000);
+                        break;
+                    }
+                    case "/stream":
+                    {
+                        ServletOutputStream output = response.getOutputStream();
+                        for (int i = 0; i < 20; i++)
+                        {
+                            output.write(("chunk" + i + "\n").getBytes());
+                            output.flush();
+                            sleep(50);
+                        }
+                        break;
+                    }
+                    default:
+                        response.getWriter().print("fast");
+                        break;
+                }
+            }
+        });
+        _server.setHandler(_deadlineHandler);
+        _server.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private static void sleep(long ms)
+    {
+        try
+        {
+            Thread.sleep(ms);
+        }
+        catch (InterruptedException x)
+        {
+            throw new RuntimeException(x);
+        }
+    }
+
+    private String get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return _connector.getResponse(request, 5, TimeUnit.SECONDS);
+    }
+
+    @Test
+    public void testWithinDeadline() throws Exception
+    {
+        HttpTester.Response response = HttpTester.parseResponse(get("/fast"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("fast"));
+        assertThat(_deadlineHandler.getExpiredCount(), is(0L));
+    }
+
+    @Test
+    public void testBlockingHandlingExpires() throws Exception
+    {
+        long start = System.nanoTime();
+        String response = get("/sleep");
+        long elapsed = TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - start);
+        // The handler is still sleeping, so the exchange is aborted without a response.
+        assertThat(response, not(containsString("HTTP/1.1")));
+        assertThat(_deadlineHandler.getExpiredCount(), is(1L));
+        // The exchange is terminated near the deadline, not when the handler returns.
+        assertThat(elapsed, lessThan(1500L));
+        assertThat(elapsed, greaterThanOrEqualTo(400L));
+    }
+
+    @Test
+    public void testAsyncHandlingExpires() throws Exception
+    {
+        long start = System.nanoTime();
+        HttpTester.Response response = HttpTester.parseResponse(get("/async"));
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(_deadlineHandler.getExpiredCount(), is(1L));
+        // The async request never completes by itself, so it was terminated by the deadline.
+        assertThat(TimeUnit.NANOSECONDS.toSeconds(System.nanoTime() - start) < 5, is(true));
+    }
+
+    @Test
+    public void testCommittedResponseIsAborted() throws Exception
+    {
+        String response = get("/stall");
+        assertThat(response, containsString("HTTP/1.1 200 OK"));
+        assertThat(response, containsString("stalled"));
+        // The response was aborted, so the terminal chunk was never sent.
+        assertThat(response, not(endsWith("0\r\n\r\n")));
+        assertThat(_deadlineHandler.getAbortedCount(), is(1L));
+    }
+
+    @Test
+    public void testStalledResponseIsAbortedAfterGracePeriod() throws Exception
+    {
+        _deadlineHandler.setGracePeriod(200);
+
+        String response = get("/stall");
+        assertThat(response, not(endsWith("0\r\n\r\n")));
+        assertThat(_deadlineHandler.getExtendedCount(), is(1L));
+        assertThat(_deadlineHandler.getAbortedCount(), is(1L));
+    }
+
+    @Test
+    public void testStreamingResponseMakingProgressCompletes() throws Exception
+    {
+        _deadlineHandler.setGracePeriod(200);
+
+        HttpTester.Response response = HttpTester.parseResponse(get("/stream"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("chunk19\n"));
+        assertThat(_deadlineHandler.getAbortedCount(), is(0L));
+    }
+}
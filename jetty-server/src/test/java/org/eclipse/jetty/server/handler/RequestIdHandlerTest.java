@@ -0,0 +1,144 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.UUID;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.slf4j.MDC;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.matchesPattern;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.nullValue;
+
+public class RequestIdHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private RequestIdHandler _requestIdHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _requestIdHandler = new RequestIdHandler();
+        _requestIdHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.getWriter().print(RequestIdHandler.getRequestId(request) + "|" + MDC.get(RequestIdHandler.DEFAULT_MDC_KEY));
+            }
+        });
+        _server.setHandler(_requestIdHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String extraHeaders) throws Exception
+    {
+        String request = "GET / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            extraHeaders +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testULIDAssignedAndPropagated() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = get("");
+        String requestId = response.get(RequestIdHandler.DEFAULT_HEADER);
+        assertThat(requestId, matchesPattern("[0-9A-HJKMNP-TV-Z]{26}"));
+        assertThat(response.getContent(), is(requestId + "|" + requestId));
+
+        // Every request has a different ID.
+        assertThat(get("").get(RequestIdHandler.DEFAULT_HEADER), not(is(requestId)));
+    }
+
+    @Test
+    public void testUUIDv7() throws Exception
+    {
+        _requestIdHandler.setGeneratorType("UUIDv7");
+        _server.start();
+
+        UUID uuid = UUID.fromString(get("").get(RequestIdHandler.DEFAULT_HEADER));
+        assertThat(uuid.version(), is(7));
+        assertThat(uuid.variant(), is(2));
+        // The most significant 48 bits are the timestamp.
+        long timestamp = uuid.getMostSignificantBits() >>> 16;
+        assertThat(Math.abs(System.currentTimeMillis() - timestamp) < 60000, is(true));
+    }
+
+    @Test
+    public void testCustomGenerator() throws Exception
+    {
+        _requestIdHandler.setGenerator(request -> "custom-" + request.getMethod());
+        _server.start();
+
+        assertThat(get("").get(RequestIdHandler.DEFAULT_HEADER), is("custom-GET"));
+    }
+
+    @Test
+    public void testRequestHeader() throws Exception
+    {
+        _server.start();
+
+        // Not trusted by default.
+        assertThat(get("X-Request-Id: upstream-id\r\n").get(RequestIdHandler.DEFAULT_HEADER), not(is("upstream-id")));
+
+        _requestIdHandler.setTrustRequestHeader(true);
+        HttpTester.Response response = get("X-Request-Id: upstream-id\r\n");
+        assertThat(response.get(RequestIdHandler.DEFAULT_HEADER), is("upstream-id"));
+        assertThat(response.getContent(), is("upstream-id|upstream-id"));
+
+        // Invalid request IDs are replaced.
+        assertThat(get("X-Request-Id: has spaces\r\n").get(RequestIdHandler.DEFAULT_HEADER), matchesPattern("[0-9A-Z]{26}"));
+        _requestIdHandler.setMaxRequestIdLength(4);
+        assertThat(get("X-Request-Id: upstream-id\r\n").get(RequestIdHandler.DEFAULT_HEADER), matchesPattern("[0-9A-Z]{26}"));
+    }
+
+    @Test
+    public void testResponseHeaderAndMDCDisabled() throws Exception
+    {
+        _requestIdHandler.setResponseHeader(null);
+        _requestIdHandler.setMdcKey(null);
+        _server.start();
+
+        HttpTester.Response response = get("");
+        assertThat(response.get(RequestIdHandler.DEFAULT_HEADER), nullValue());
+        assertThat(response.getContent(), matchesPattern("[0-9A-Z]{26}\\|null"));
+    }
+}
@@ -0,0 +1,213 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.ByteArrayInputStream;
+import java.io.InputStream;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.attribute.FileTime;
+import java.util.zip.GZIPInputStream;
+import java.util.zip.GZIPOutputStream;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.CompressedContentCache;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class ResourceHandlerPrecompressedTest
+{
+    private static final String CONTENT = "Lorem ipsum dolor sit amet, consectetur adipiscing elit. ".repeat(64);
+
+    public WorkDir _workDir;
+    private Path _docRoot;
+    private Server _server;
+    private LocalConnector _connector;
+    private ResourceHandler _resourceHandler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _docRoot = _workDir.getEmptyPathDir();
+        Files.writeString(_docRoot.resolve("file.txt"), CONTENT);
+
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _resourceHandler = new ResourceHandler();
+        _resourceHandler.setResourceBase(_docRoot.toString());
+        ContextHandler context = new ContextHandler("/");
+        context.setHandler(_resourceHandler);
+        _server.setHandler(context);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path, String headers) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    private static String gunzip(byte[] bytes) throws Exception
+    {
+        try (InputStream input = new GZIPInputStream(new ByteArrayInputStream(bytes)))
+        {
+            return new String(input.readAllBytes(), StandardCharsets.UTF_8);
+        }
+    }
+
+    @Test
+    public void testPrecompressedSibling() throws Exception
+    {
+        try (GZIPOutputStream output = new GZIPOutputStream(Files.newOutputStream(_docRoot.resolve("file.txt.gz"))))
+        {
+            output.write(CONTENT.getBytes(StandardCharsets.UTF_8));
+        }
+        _resourceHandler.setPrecompressed(true);
+        _server.start();
+
+        HttpTester.Response response = get("/file.txt", "Accept-Encoding: br, gzip\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+        assertThat(response.get(HttpHeader.VARY), containsString("Accept-Encoding"));
+        assertThat(response.get(HttpHeader.ETAG), endsWith("--gzip\""));
+        assertThat(gunzip(response.getContentBytes()), is(CONTENT));
+
+        response = get("/file.txt", "Accept-Encoding: identity\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.get(HttpHeader.VARY), containsString("Accept-Encoding"));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testCompressedContentCache() throws Exception
+    {
+        CompressedContentCache cache = new CompressedContentCache();
+        cache.setEncodings("gzip");
+        cache.setMinHits(2);
+        cache.setExecutor(Runnable::run);
+        _resourceHandler.setCompressedContentCache(cache);
+        _server.start();
+
+        // Not hot enough yet.
+        HttpTester.Response response = get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.getContent(), is(CONTENT));
+        assertThat(cache.getCachedFiles(), is(0));
+
+        // The second hit compresses the content.
+        response = get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(response.getContent(), is(CONTENT));
+        assertThat(cache.getCachedFiles(), is(1));
+        assertThat(cache.getCompressions(), is(1L));
+
+        response = get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+        assertThat(response.get(HttpHeader.VARY), containsString("Accept-Encoding"));
+        assertThat(response.get(HttpHeader.ETAG), endsWith("--gzip\""));
+        assertThat(response.getContentBytes().length, is((int)cache.getCacheSize()));
+        assertThat(gunzip(response.getContentBytes()), is(CONTENT));
+
+        response = get("/file.txt", "");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.getContent(), is(CONTENT));
+
+        // Ranges are served from the original content.
+        response = get("/file.txt", "Accept-Encoding: gzip\r\nRange: bytes=0-4\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.PARTIAL_CONTENT_206));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.getContent(), is(CONTENT.substring(0, 5)));
+    }
+
+    @Test
+    public void testCompressedContentCacheInvalidation() throws Exception
+    {
+        CompressedContentCache cache = new CompressedContentCache();
+        cache.setEncodings("gzip");
+        cache.setMinHits(1);
+        cache.setExecutor(Runnable::run);
+        _resourceHandler.setCompressedContentCache(cache);
+        _server.start();
+
+        get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(cache.getCachedFiles(), is(1));
+
+        String content = CONTENT.toUpperCase();
+        Path file = _docRoot.resolve("file.txt");
+        Files.writeString(file, content + "!");
+        Files.setLastModifiedTime(file, FileTime.fromMillis(System.currentTimeMillis() + 10_000));
+
+        // The stale variant is not served, and the content is compressed again.
+        HttpTester.Response response = get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.getContent(), is(content + "!"));
+        assertThat(cache.getCompressions(), is(2L));
+
+        response = get("/file.txt", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+        assertThat(gunzip(response.getContentBytes()), is(content + "!"));
+    }
+
+    @Test
+    public void testCompressedContentCacheBudget() throws Exception
+    {
+        for (int i = 0; i < 4; ++i)
+        {
+            Files.writeString(_docRoot.resolve("file" + i + ".txt"), CONTENT + i);
+        }
+        CompressedContentCache cache = new CompressedContentCache();
+        cache.setEncodings("gzip");
+        cache.setMinHits(1);
+        cache.setMaxCachedFiles(2);
+        cache.setExecutor(Runnable::run);
+        _resourceHandler.setCompressedContentCache(cache);
+        _server.start();
+
+        for (int i = 0; i < 4; ++i)
+        {
+            get("/file" + i + ".txt", "Accept-Encoding: gzip\r\n");
+        }
+        assertThat(cache.getCachedFiles(), is(2));
+        assertThat(cache.getCompressions(), is(4L));
+
+        // Non compressible mime types are not cached.
+        Files.write(_docRoot.resolve("image.png"), CONTENT.getBytes(StandardCharsets.UTF_8));
+        HttpTester.Response response = get("/image.png", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(cache.getCompressions(), is(4L));
+    }
+}
@@ -268,6 +268,43 @@ public class ResourceHandlerTest
         }
     }
 
+    @Test
+    public void testBiggerSendFile() throws Exception
+    {
+        File bigger = MavenTestingUtils.getTargetFile("test-classes/simple/bigger.txt");
+        long responses = _resourceHandler.getSendFileResponses();
+        long bytes = _resourceHandler.getSendFileBytes();
+        try
+        {
+            _resourceHandler.setSendFileThreshold(1024);
+            try (Socket socket = new Socket("localhost", _connector.getLocalPort()))
+            {
+                socket.getOutputStream().write("GET /resource/bigger.txt HTTP/1.0\n\n".getBytes());
+                HttpTester.Response response = HttpTester.parseResponse(socket.getInputStream());
+                assertThat(response.getStatus(), equalTo(200));
+                assertThat(response.get(CONTENT_LENGTH), equalTo(Long.toString(bigger.length())));
+                assertThat(response.getContent(), Matchers.startsWith("     1\tThis is a big file"));
+                assertThat(response.getContent(), Matchers.endsWith("   400\tThis is a big file" + LN));
+                assertThat(response.getContentBytes().length, equalTo((int)bigger.length()));
+            }
+            assertThat(_resourceHandler.getSendFileResponses(), equalTo(responses + 1));
+            assertThat(_resourceHandler.getSendFileBytes(), equalTo(bytes + bigger.length()));
+
+            // Files smaller than the threshold and non socket transports are not zero-copy.
+            HttpTester.Response response = HttpTester.parseResponse(
+                _local.getResponse("GET /resource/simple.txt HTTP/1.0\r\n\r\n"));
+            assertThat(response.getStatus(), equalTo(200));
+            response = HttpTester.parseResponse(
+                _local.getResponse("GET /resource/bigger.txt HTTP/1.0\r\n\r\n"));
+            assertThat(response.getStatus(), equalTo(200));
+            assertThat(_resourceHandler.getSendFileResponses(), equalTo(responses + 1));
+        }
+        finally
+        {
+            _resourceHandler.setSendFileThreshold(-1);
+        }
+    }
+
     @Test
     public void testWelcome() throws Exception
     {
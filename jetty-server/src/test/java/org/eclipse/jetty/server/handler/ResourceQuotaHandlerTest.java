@@ -0,0 +1,205 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class ResourceQuotaHandlerTest
+{
+    private Server _server;
+    private LocalConnector _connector;
+    private ResourceQuotaHandler _quotaHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        ContextHandler context = new ContextHandler("/");
+        _quotaHandler = new ResourceQuotaHandler();
+        context.setHandler(_quotaHandler);
+        _server.setHandler(context);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private void start(AbstractHandler handler) throws Exception
+    {
+        _quotaHandler.setHandler(handler);
+        _server.start();
+    }
+
+    @Test
+    public void testMaxConcurrentRequests() throws Exception
+    {
+        CountDownLatch handleLatch = new CountDownLatch(1);
+        CountDownLatch releaseLatch = new CountDownLatch(1);
+        _quotaHandler.setMaxConcurrentRequests(1);
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                handleLatch.countDown();
+                try
+                {
+                    releaseLatch.await(5, TimeUnit.SECONDS);
+                }
+                catch (InterruptedException x)
+                {
+                    throw new IOException(x);
+                }
+            }
+        });
+
+        String request = "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        LocalConnector.LocalEndPoint first = _connector.executeRequest(request);
+        assertTrue(handleLatch.await(5, TimeUnit.SECONDS));
+        assertThat(_quotaHandler.getRequests(), is(1L));
+
+        HttpTester.Response rejected = HttpTester.parseResponse(_connector.getResponse(request));
+        assertThat(rejected.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(rejected.get(HttpHeader.RETRY_AFTER), notNullValue());
+
+        releaseLatch.countDown();
+        assertThat(HttpTester.parseResponse(first.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(_quotaHandler.getRequests(), is(0L));
+        assertThat(_quotaHandler.getRejectedRequests(), is(1L));
+    }
+
+    @Test
+    public void testRequestContentQuota() throws Exception
+    {
+        _quotaHandler.setMaxRequestContentBytes(10);
+        _quotaHandler.setRejectStatus(HttpStatus.TOO_MANY_REQUESTS_429);
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                IO.readBytes(request.getInputStream());
+            }
+        });
+
+        // Rejected upfront because of the Content-Length.
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 16\r\nConnection: close\r\n\r\n" +
+            "0123456789ABCDEF"));
+        assertThat(response.getStatus(), is(HttpStatus.TOO_MANY_REQUESTS_429));
+
+        // Rejected while reading the chunked content.
+        response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n" +
+            "10\r\n0123456789ABCDEF\r\n0\r\n\r\n"));
+        assertThat(response.getStatus(), is(HttpStatus.TOO_MANY_REQUESTS_429));
+
+        // Content within the quota.
+        response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 8\r\nConnection: close\r\n\r\n" +
+            "01234567"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        assertThat(_quotaHandler.getRejectedContent(), is(2L));
+        assertThat(_quotaHandler.getRequestContentBytes(), is(0L));
+    }
+
+    @Test
+    public void testMultiPartQuota() throws Exception
+    {
+        _quotaHandler.setMaxMultiPartBytes(10);
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                IO.readBytes(request.getInputStream());
+            }
+        });
+
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Type: multipart/form-data; boundary=X\r\nContent-Length: 16\r\nConnection: close\r\n\r\n" +
+            "0123456789ABCDEF"));
+        assertThat(response.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+
+        // Other content is not subject to the multipart quota.
+        response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Type: text/plain\r\nContent-Length: 16\r\nConnection: close\r\n\r\n" +
+            "0123456789ABCDEF"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+
+        assertThat(_quotaHandler.getRejectedMultiPart(), is(1L));
+        assertThat(_quotaHandler.getMultiPartBytes(), is(0L));
+    }
+
+    @Test
+    public void testMaxOutputRate() throws Exception
+    {
+        _quotaHandler.setMaxOutputRate(1000);
+        _quotaHandler.setMaxOutputBurst(500);
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                for (int i = 0; i < 3; ++i)
+                {
+                    response.getOutputStream().write(new byte[500]);
+                    response.flushBuffer();
+                }
+            }
+        });
+
+        long begin = NanoTime.now();
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(
+            "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n", 5, TimeUnit.SECONDS));
+        long elapsed = NanoTime.millisSince(begin);
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes().length, is(1500));
+        // 1500 bytes at 1000 bytes/s, the first 500 bytes are the burst and are not delayed.
+        assertThat(elapsed, greaterThanOrEqualTo(900L));
+        assertThat(_quotaHandler.getDelayedWrites(), greaterThanOrEqualTo(2L));
+    }
+}
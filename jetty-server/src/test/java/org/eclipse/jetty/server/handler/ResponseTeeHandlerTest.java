@@ -0,0 +1,190 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.List;
+import java.util.stream.Collectors;
+import java.util.stream.Stream;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.Callback;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class ResponseTeeHandlerTest
+{
+    public WorkDir workDir;
+    private Server _server;
+    private LocalConnector _connector;
+    private ResponseTeeHandler _teeHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _teeHandler = new ResponseTeeHandler();
+        _teeHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                if (target.endsWith(".json"))
+                    response.setContentType("application/json");
+                else
+                    response.setContentType("text/plain;charset=utf-8");
+                // Write in multiple chunks.
+                for (int i = 0; i < 4; ++i)
+                {
+                    response.getOutputStream().write(("chunk" + i + ";").getBytes(StandardCharsets.UTF_8));
+                    response.flushBuffer();
+                }
+            }
+        });
+        _server.setHandler(_teeHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testResponseRecorded() throws Exception
+    {
+        ResponseTeeHandler.MemorySink sink = new ResponseTeeHandler.MemorySink();
+        _teeHandler.setSink(sink);
+        _server.start();
+
+        HttpTester.Response response = get("/path");
+        assertThat(response.getStatus(), is(200));
+        assertThat(response.getContent(), is("chunk0;chunk1;chunk2;chunk3;"));
+
+        List<ResponseTeeHandler.Recorded> recorded = sink.getRecorded();
+        assertThat(recorded, hasSize(1));
+        ResponseTeeHandler.Recorded recording = recorded.get(0);
+        assertThat(recording.getMethod(), is("GET"));
+        assertThat(recording.getURI(), is("/path"));
+        assertThat(recording.getStatus(), is(200));
+        assertThat(recording.getContentType(), containsString("text/plain"));
+        assertThat(recording.getContentAsString(), is("chunk0;chunk1;chunk2;chunk3;"));
+        assertThat(recording.isComplete(), is(true));
+        assertThat(recording.getFailure(), nullValue());
+        assertThat(_teeHandler.getRecordings(), is(1L));
+    }
+
+    @Test
+    public void testMaxContentLength() throws Exception
+    {
+        ResponseTeeHandler.MemorySink sink = new ResponseTeeHandler.MemorySink();
+        _teeHandler.setSink(sink);
+        _teeHandler.setMaxContentLength(10);
+        _server.start();
+
+        HttpTester.Response response = get("/path");
+        // The client receives the whole content.
+        assertThat(response.getContent(), is("chunk0;chunk1;chunk2;chunk3;"));
+
+        ResponseTeeHandler.Recorded recording = sink.getRecorded().get(0);
+        assertThat(recording.getContentAsString(), is("chunk0;chu"));
+        assertThat(recording.isComplete(), is(true));
+    }
+
+    @Test
+    public void testMimeTypes() throws Exception
+    {
+        ResponseTeeHandler.MemorySink sink = new ResponseTeeHandler.MemorySink();
+        _teeHandler.setSink(sink);
+        _teeHandler.includeMimeTypes("application/json");
+        _server.start();
+
+        assertThat(get("/path").getStatus(), is(200));
+        assertThat(sink.getRecorded(), hasSize(0));
+
+        assertThat(get("/path.json").getStatus(), is(200));
+        assertThat(sink.getRecorded(), hasSize(1));
+        assertThat(sink.getRecorded().get(0).getURI(), is("/path.json"));
+    }
+
+    @Test
+    public void testSinkFailureDoesNotFailResponse() throws Exception
+    {
+        _teeHandler.setSink((request, response) -> new ResponseTeeHandler.Recording()
+        {
+            @Override
+            public void write(ByteBuffer content, boolean last, Callback callback)
+            {
+                callback.failed(new IOException("explicitly_thrown_by_test"));
+            }
+        });
+        _server.start();
+
+        HttpTester.Response response = get("/path");
+        assertThat(response.getStatus(), is(200));
+        assertThat(response.getContent(), is("chunk0;chunk1;chunk2;chunk3;"));
+        assertThat(_teeHandler.getRecordingFailures(), is(1L));
+    }
+
+    @Test
+    public void testFileSink() throws Exception
+    {
+        Path directory = workDir.getEmptyPathDir().resolve("responses");
+        _teeHandler.setSink(new ResponseTeeHandler.FileSink(directory));
+        _server.start();
+
+        assertThat(get("/path").getStatus(), is(200));
+
+        List<Path> files;
+        try (Stream<Path> stream = Files.list(directory))
+        {
+            files = stream.collect(Collectors.toList());
+        }
+        assertThat(files, hasSize(1));
+        String recorded = Files.readString(files.get(0));
+        assertThat(recorded, containsString("GET /path HTTP/1.1\r\n"));
+        assertThat(recorded, containsString("Status: 200\r\n"));
+        assertThat(recorded, endsWith("\r\n\r\nchunk0;chunk1;chunk2;chunk3;"));
+    }
+}
@@ -0,0 +1,188 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.Map;
+import java.util.UUID;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class RouterHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private RouterHandler router;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        router = new RouterHandler();
+        server.setHandler(router);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private static AbstractHandler reply(String name)
+    {
+        return new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                response.getWriter().print(name + " " + request.getAttribute(RouterHandler.PATH_PARAMETERS_ATTRIBUTE));
+            }
+        };
+    }
+
+    private HttpTester.Response request(String method, String path) throws Exception
+    {
+        String request = method + " " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testLiteralBeforeVariable() throws Exception
+    {
+        router.get("/users/{id}", reply("user"));
+        router.get("/users/me", reply("me"));
+        server.start();
+
+        assertThat(request("GET", "/users/me").getContent(), is("me {}"));
+        assertThat(request("GET", "/users/42").getContent(), is("user {id=42}"));
+        assertThat(request("GET", "/other").getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testTypedParameters()
+    {
+        router.get("/orders/{id:int}/items/{item:uuid}", reply("item"));
+        router.get("/files/{name}.{ext}", reply("file"));
+
+        UUID uuid = UUID.randomUUID();
+        RouterHandler.Match match = router.match("GET", "/orders/7/items/" + uuid);
+        assertThat(match.getParameters().get("id"), instanceOf(Integer.class));
+        assertThat(match.getParameters().get("id"), is(7));
+        assertThat(match.getParameters().get("item"), is(uuid));
+
+        // Values that cannot be converted do not match.
+        assertThat(router.match("GET", "/orders/x/items/" + uuid), nullValue());
+
+        match = router.match("GET", "/files/report.tar.gz");
+        assertThat(match.getParameters(), is(Map.of("name", "report", "ext", "tar.gz")));
+    }
+
+    @Test
+    public void testReservedExpansion() throws Exception
+    {
+        router.get("/static{+path}", reply("static"));
+        server.start();
+
+        assertThat(request("GET", "/static/css/site.css").getContent(), is("static {path=/css/site.css}"));
+        assertThat(request("GET", "/static").getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThrows(IllegalArgumentException.class, () -> router.get("/a/{+path}/b", reply("invalid")));
+    }
+
+    @Test
+    public void testMethodDispatch() throws Exception
+    {
+        router.get("/users/{id}", reply("get"));
+        router.delete("/users/{id}", reply("delete"));
+        router.addRoute("/any/{id}", reply("any"));
+        server.start();
+
+        assertThat(request("DELETE", "/users/1").getContent(), is("delete {id=1}"));
+        assertThat(request("HEAD", "/users/1").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request("PATCH", "/any/1").getContent(), is("any {id=1}"));
+
+        HttpTester.Response response = request("POST", "/users/1");
+        assertThat(response.getStatus(), is(HttpStatus.METHOD_NOT_ALLOWED_405));
+        assertThat(response.get(HttpHeader.ALLOW), is("GET, HEAD, DELETE"));
+
+        assertThrows(IllegalArgumentException.class, () -> router.get("/users/{name}", reply("duplicate")));
+    }
+
+    @Test
+    public void testTrailingSlashIgnore() throws Exception
+    {
+        router.setTrailingSlash(RouterHandler.TrailingSlash.IGNORE);
+        router.get("/users", reply("users"));
+        router.get("/docs/", reply("docs"));
+        server.start();
+
+        assertThat(request("GET", "/users/").getContent(), is("users {}"));
+        assertThat(request("GET", "/docs").getContent(), is("docs {}"));
+    }
+
+    @Test
+    public void testTrailingSlashRedirect() throws Exception
+    {
+        router.setTrailingSlash(RouterHandler.TrailingSlash.REDIRECT);
+        router.get("/users", reply("users"));
+        router.post("/users", reply("create"));
+        server.start();
+
+        HttpTester.Response response = request("GET", "/users/?page=2");
+        assertThat(response.getStatus(), is(HttpStatus.MOVED_PERMANENTLY_301));
+        assertThat(response.get(HttpHeader.LOCATION), containsString("/users?page=2"));
+
+        response = request("POST", "/users/");
+        assertThat(response.getStatus(), is(HttpStatus.PERMANENT_REDIRECT_308));
+    }
+
+    @Test
+    public void testParameterAttributes() throws Exception
+    {
+        router.addParameterType("upper", value -> value.toUpperCase());
+        router.get("/greet/{name:upper}", new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.getWriter().print("hello " + request.getAttribute("name"));
+            }
+        });
+        server.start();
+
+        assertThat(request("GET", "/greet/jetty").getContent(), is("hello JETTY"));
+        assertThrows(IllegalArgumentException.class, () -> router.get("/x/{id:unknown}", reply("invalid")));
+    }
+}
@@ -0,0 +1,151 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.HttpConnectionFactory;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class SecuredRedirectHandlerHstsTest
+{
+    private Server server;
+    private LocalConnector plainConnector;
+    private LocalConnector secureConnector;
+
+    private void start(SecuredRedirectHandler securedRedirectHandler) throws Exception
+    {
+        server = new Server();
+
+        HttpConfiguration plainConfig = new HttpConfiguration();
+        plainConfig.setSecurePort(8443);
+        plainConfig.setSecureScheme("https");
+        plainConnector = new LocalConnector(server, new HttpConnectionFactory(plainConfig));
+        server.addConnector(plainConnector);
+
+        HttpConfiguration secureConfig = new HttpConfiguration(plainConfig);
+        secureConfig.addCustomizer((connector, channelConfig, request) -> request.setSecure(true));
+        secureConnector = new LocalConnector(server, new HttpConnectionFactory(secureConfig));
+        server.addConnector(secureConnector);
+
+        securedRedirectHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+            }
+        });
+        server.setHandler(securedRedirectHandler);
+        server.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (server != null)
+            server.stop();
+    }
+
+    private static HttpTester.Response request(LocalConnector connector, String method, String host, String path) throws Exception
+    {
+        String request = method + " " + path + " HTTP/1.1\r\n" +
+            "Host: " + host + "\r\n" +
+            "Content-Length: 0\r\n" +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testHstsHeader() throws Exception
+    {
+        SecuredRedirectHandler handler = new SecuredRedirectHandler();
+        handler.setHstsMaxAge(SecuredRedirectHandler.HSTS_PRELOAD_MIN_MAX_AGE);
+        handler.setHstsIncludeSubDomains(true);
+        handler.setHstsPreload(true);
+        handler.addExcludedHost("internal.example.com");
+        start(handler);
+
+        HttpTester.Response response = request(secureConnector, "GET", "www.example.com", "/");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.STRICT_TRANSPORT_SECURITY), is("max-age=31536000; includeSubDomains; preload"));
+
+        // No HSTS header for excluded hosts.
+        response = request(secureConnector, "GET", "internal.example.com", "/");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.STRICT_TRANSPORT_SECURITY), nullValue());
+
+        // No HSTS header over plaintext.
+        response = request(plainConnector, "GET", "www.example.com", "/");
+        assertThat(response.getStatus(), is(HttpStatus.FOUND_302));
+        assertThat(response.get(HttpHeader.LOCATION), is("https://www.example.com:8443/"));
+        assertThat(response.get(HttpHeader.STRICT_TRANSPORT_SECURITY), nullValue());
+    }
+
+    @Test
+    public void testInvalidPreload()
+    {
+        SecuredRedirectHandler handler = new SecuredRedirectHandler();
+        handler.setHstsMaxAge(3600);
+        handler.setHstsIncludeSubDomains(true);
+        handler.setHstsPreload(true);
+        assertThrows(IllegalStateException.class, () -> start(handler));
+    }
+
+    @Test
+    public void testExclusions() throws Exception
+    {
+        SecuredRedirectHandler handler = new SecuredRedirectHandler();
+        handler.addExcludedHost("*.internal.example.com");
+        handler.addExcludedPath("/.well-known/acme-challenge/*");
+        handler.addExcludedPath("/health");
+        start(handler);
+
+        assertThat(request(plainConnector, "GET", "www.example.com", "/.well-known/acme-challenge/token").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request(plainConnector, "GET", "www.example.com", "/health").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request(plainConnector, "GET", "node1.internal.example.com", "/").getStatus(), is(HttpStatus.OK_200));
+        assertThat(request(plainConnector, "GET", "www.example.com", "/health/other").getStatus(), is(HttpStatus.FOUND_302));
+        assertThat(request(plainConnector, "GET", "internal.example.com.evil", "/").getStatus(), is(HttpStatus.FOUND_302));
+    }
+
+    @Test
+    public void testRejectNonIdempotentMethods() throws Exception
+    {
+        SecuredRedirectHandler handler = new SecuredRedirectHandler(HttpStatus.TEMPORARY_REDIRECT_307);
+        handler.setRejectNonIdempotentMethods(true);
+        start(handler);
+
+        assertThat(request(plainConnector, "POST", "www.example.com", "/").getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(request(plainConnector, "PUT", "www.example.com", "/").getStatus(), is(HttpStatus.TEMPORARY_REDIRECT_307));
+        assertThat(request(plainConnector, "GET", "www.example.com", "/").getStatus(), is(HttpStatus.TEMPORARY_REDIRECT_307));
+        // Secure requests are not affected.
+        assertThat(request(secureConnector, "POST", "www.example.com", "/").getStatus(), is(HttpStatus.OK_200));
+    }
+}
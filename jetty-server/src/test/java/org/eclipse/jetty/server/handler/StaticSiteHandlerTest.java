@@ -0,0 +1,173 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.nio.file.Files;
+import java.nio.file.Path;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.resource.Resource;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.endsWith;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class StaticSiteHandlerTest
+{
+    public WorkDir _workDir;
+    private Path _docRoot;
+    private Server _server;
+    private LocalConnector _connector;
+    private StaticSiteHandler _siteHandler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _docRoot = _workDir.getEmptyPathDir().resolve("site");
+        Files.createDirectories(_docRoot);
+        Files.writeString(_docRoot.resolve("index.html"), "home");
+        Files.writeString(_docRoot.resolve("about.html"), "about");
+
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _siteHandler = new StaticSiteHandler();
+        _siteHandler.setResourceBase(_docRoot.toString());
+        ContextHandler context = new ContextHandler("/");
+        context.setHandler(_siteHandler);
+        _server.setHandler(context);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testCleanUrlsAndNotFoundPage() throws Exception
+    {
+        Files.writeString(_docRoot.resolve("404.html"), "not here");
+        _server.start();
+
+        HttpTester.Response response = get("/about");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("about"));
+
+        response = get("/missing");
+        assertThat(response.getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(response.getContent(), is("not here"));
+        assertThat(response.get(HttpHeader.CACHE_CONTROL), is("no-store"));
+
+        // The error page is served regardless of the conditional headers.
+        String request = "GET /missing HTTP/1.1\r\nHost: localhost\r\nIf-Modified-Since: Thu, 01 Jan 2099 00:00:00 GMT\r\nConnection: close\r\n\r\n";
+        response = HttpTester.parseResponse(_connector.getResponse(request));
+        assertThat(response.getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(response.getContent(), is("not here"));
+    }
+
+    @Test
+    public void testRedirectsFile() throws Exception
+    {
+        Files.createDirectories(_docRoot.resolve("posts"));
+        Files.writeString(_docRoot.resolve("posts/2024-hello.html"), "hello post");
+        Files.writeString(_docRoot.resolve("exists.txt"), "exists");
+        Files.writeString(_docRoot.resolve("forced.txt"), "forced");
+        Files.writeString(_docRoot.resolve("gone.html"), "gone");
+        Files.writeString(_docRoot.resolve("_redirects"), String.join("\n",
+            "# Redirects",
+            "/old/*           /new/:splat",
+            "/blog/:year/:slug /posts/:year-:slug.html 200",
+            "/exists.txt      /other.txt 302",
+            "/forced.txt      /other.txt 302!",
+            "/removed         /gone.html 410",
+            "not a rule"));
+        _server.start();
+
+        assertThat(_siteHandler.getRules().size(), is(5));
+
+        HttpTester.Response response = get("/old/a/b?x=1");
+        assertThat(response.getStatus(), is(HttpStatus.MOVED_PERMANENTLY_301));
+        assertThat(response.get(HttpHeader.LOCATION), endsWith("/new/a/b?x=1"));
+
+        response = get("/blog/2024/hello");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello post"));
+
+        // Existing resources shadow the rules, unless forced.
+        response = get("/exists.txt");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("exists"));
+        response = get("/forced.txt");
+        assertThat(response.getStatus(), is(HttpStatus.FOUND_302));
+
+        response = get("/removed");
+        assertThat(response.getStatus(), is(HttpStatus.GONE_410));
+        assertThat(response.getContent(), is("gone"));
+
+        // The redirects file is not served.
+        assertThat(get("/_redirects").getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testAliasAndCacheControl() throws Exception
+    {
+        Path docs = _workDir.getPath().resolve("docs");
+        Files.createDirectories(docs);
+        Files.writeString(docs.resolve("guide.txt"), "guide");
+        Files.writeString(_docRoot.resolve("style.css"), "body{}");
+        _siteHandler.addAlias("/docs", Resource.newResource(docs));
+        _siteHandler.addCacheControl("*.css", "max-age=3600");
+        _server.start();
+
+        HttpTester.Response response = get("/docs/guide.txt");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("guide"));
+        assertThat(response.get(HttpHeader.CACHE_CONTROL), nullValue());
+
+        response = get("/style.css");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CACHE_CONTROL), is("max-age=3600"));
+    }
+
+    @Test
+    public void testInvalidRules()
+    {
+        assertThat(StaticSiteHandler.Rule.parse("  # comment"), nullValue());
+        assertThat(StaticSiteHandler.Rule.parse("/a /b 307!").toString(), is("/a /b 307!"));
+        assertThrows(IllegalArgumentException.class, () -> StaticSiteHandler.Rule.parse("/a"));
+        assertThrows(IllegalArgumentException.class, () -> StaticSiteHandler.Rule.parse("/a /b 100"));
+        assertThrows(IllegalArgumentException.class, () -> StaticSiteHandler.Rule.parse("/*/a /b"));
+        assertThrows(IllegalArgumentException.class, () -> StaticSiteHandler.Rule.parse("/a https://example.com 200"));
+    }
+}
@@ -14,6 +14,7 @@
 package org.eclipse.jetty.server.handler;
 
 import java.io.IOException;
+import java.util.Map;
 import java.util.Objects;
 import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.CyclicBarrier;
@@ -40,11 +41,14 @@ import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
 
 import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
 import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.lessThan;
 import static org.junit.jupiter.api.Assertions.assertEquals;
 import static org.junit.jupiter.api.Assertions.assertFalse;
 import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertNull;
 import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
@@ -872,6 +876,110 @@ public class StatisticsHandlerTest
      * handle() is fully executed before asserting its values in the tests, to avoid race conditions with the
      * tests' code where the test executes but the statistics handler has not finished yet.
      */
+    @Test
+    public void testRequestTimePercentiles() throws Exception
+    {
+        _statsHandler.setRequestTimeWindow(60000);
+        _statsHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String path, Request request, HttpServletRequest httpRequest, HttpServletResponse httpResponse) throws IOException
+            {
+                request.setHandled(true);
+                if (path.startsWith("/slow"))
+                {
+                    try
+                    {
+                        Thread.sleep(200);
+                    }
+                    catch (InterruptedException x)
+                    {
+                        throw new IOException(x);
+                    }
+                }
+            }
+        });
+        _server.start();
+
+        assertThrows(IllegalStateException.class, () -> _statsHandler.setRequestTimeWindow(1000));
+
+        for (int i = 0; i < 98; i++)
+        {
+            _connector.getResponse("GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n");
+        }
+        for (int i = 0; i < 2; i++)
+        {
+            _connector.getResponse("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n");
+        }
+
+        assertEquals(100, _statsHandler.getRequestTimeHistogram().getCount());
+        assertThat(_statsHandler.getRequestTimePercentile50(), lessThan(100L));
+        assertThat(_statsHandler.getRequestTimePercentile95(), lessThan(100L));
+        assertThat(_statsHandler.getRequestTimePercentile99(), greaterThanOrEqualTo(200L));
+        assertThat(_statsHandler.toStatsHTML(), containsString("Request time percentiles"));
+
+        _statsHandler.statsReset();
+        assertEquals(0, _statsHandler.getRequestTimeHistogram().getCount());
+        assertEquals(0, _statsHandler.getRequestTimePercentile99());
+    }
+
+    @Test
+    public void testRequestTimePercentilesDisabledByDefault() throws Exception
+    {
+        _statsHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String path, Request request, HttpServletRequest httpRequest, HttpServletResponse httpResponse)
+            {
+                request.setHandled(true);
+            }
+        });
+        _server.start();
+
+        _connector.getResponse("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n");
+
+        assertNull(_statsHandler.getRequestTimeHistogram());
+        assertEquals(0, _statsHandler.getRequestTimePercentile50());
+        assertTrue(_statsHandler.getPathStatistics().isEmpty());
+    }
+
+    @Test
+    public void testPathStatistics() throws Exception
+    {
+        _statsHandler.setRequestTimeWindow(60000);
+        _statsHandler.setPathDepth(2);
+        _statsHandler.setMaxPaths(3);
+        _statsHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String path, Request request, HttpServletRequest httpRequest, HttpServletResponse httpResponse)
+            {
+                request.setHandled(true);
+                if (path.startsWith("/api/fail"))
+                    httpResponse.setStatus(HttpStatus.INTERNAL_SERVER_ERROR_500);
+            }
+        });
+        _server.start();
+
+        for (String path : new String[]{"/api/users/1", "/api/users/2", "/api/fail/1", "/", "/x/y/z", "/a/b"})
+        {
+            _connector.getResponse("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n\r\n");
+        }
+
+        Map<String, StatisticsHandler.PathStatistics> paths = _statsHandler.getPathStatistics();
+        assertThat(paths.keySet(), contains("/", "/api/fail", "/api/users", StatisticsHandler.OTHER_PATHS));
+        assertEquals(2, paths.get("/api/users").getRequests());
+        assertEquals(0, paths.get("/api/users").getFailures());
+        assertEquals(2, paths.get("/api/users").getRequestTimeHistogram().getCount());
+        assertEquals(1, paths.get("/api/fail").getFailures());
+        // The paths beyond the max are accounted together.
+        assertEquals(2, paths.get(StatisticsHandler.OTHER_PATHS).getRequests());
+        assertThat(_statsHandler.getPathStatisticsDescriptions().get("/api/users"), containsString("requests=2"));
+
+        _statsHandler.statsReset();
+        assertTrue(_statsHandler.getPathStatistics().isEmpty());
+    }
+
     private static class LatchHandler extends HandlerWrapper
     {
         private volatile CountDownLatch _latch = new CountDownLatch(1);
@@ -16,8 +16,10 @@ package org.eclipse.jetty.server.handler;
 import java.io.IOException;
 import java.net.Socket;
 import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.AsyncContext;
 import javax.servlet.ServletException;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
@@ -36,7 +38,9 @@ import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
 
 import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertTrue;
 
 public class ThreadLimitHandlerTest
 {
@@ -238,4 +242,175 @@ public class ThreadLimitHandlerTest
         }
         assertThat(count.get(), is(0));
     }
+
+    @Test
+    public void testTrustedProxies() throws Exception
+    {
+        AtomicReference<String> last = new AtomicReference<>();
+        ThreadLimitHandler handler = new ThreadLimitHandler("X-Forwarded-For")
+        {
+            @Override
+            protected int getThreadLimit(String ip)
+            {
+                last.set(ip);
+                return super.getThreadLimit(ip);
+            }
+        };
+        handler.addTrustedProxies("0.0.0.0", "10.0.0.0/8");
+        _server.setHandler(handler);
+        _server.start();
+
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\n\r\n");
+        assertThat(last.get(), is("0.0.0.0"));
+
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\nX-Forwarded-For: 1.2.3.4\r\n\r\n");
+        assertThat(last.get(), is("1.2.3.4"));
+
+        // The trusted proxies are skipped, but not the addresses added by the client.
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\nX-Forwarded-For: 6.6.6.6, 1.2.3.4\r\nX-Forwarded-For: 10.0.0.1\r\n\r\n");
+        assertThat(last.get(), is("1.2.3.4"));
+
+        // All the hops are trusted.
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\nX-Forwarded-For: 10.0.0.2, 10.0.0.1\r\n\r\n");
+        assertThat(last.get(), is("10.0.0.2"));
+
+        // The trusted proxy does not know the remote address.
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\nX-Forwarded-For: 1.2.3.4, unknown, 10.0.0.1\r\n\r\n");
+        assertThat(last.get(), is("10.0.0.1"));
+    }
+
+    @Test
+    public void testUntrustedPeer() throws Exception
+    {
+        AtomicReference<String> last = new AtomicReference<>();
+        ThreadLimitHandler handler = new ThreadLimitHandler("Forwarded")
+        {
+            @Override
+            protected int getThreadLimit(String ip)
+            {
+                last.set(ip);
+                return super.getThreadLimit(ip);
+            }
+        };
+        handler.addTrustedProxy("10.0.0.0/8");
+        _server.setHandler(handler);
+        _server.start();
+
+        // The request is not received from a trusted proxy, so the header is ignored.
+        last.set(null);
+        _local.getResponse("GET / HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n");
+        assertThat(last.get(), is("0.0.0.0"));
+    }
+
+    @Test
+    public void testAsyncRequestHoldsPermit() throws Exception
+    {
+        ThreadLimitHandler handler = new ThreadLimitHandler("Forwarded");
+        handler.setThreadLimit(1);
+        CountDownLatch saturated = new CountDownLatch(1);
+        CountDownLatch unsaturated = new CountDownLatch(1);
+        handler.addListener(new ThreadLimitHandler.Listener()
+        {
+            @Override
+            public void onSaturated(ThreadLimitHandler handler, String ip)
+            {
+                if ("1.2.3.4".equals(ip))
+                    saturated.countDown();
+            }
+
+            @Override
+            public void onUnsaturated(ThreadLimitHandler handler, String ip)
+            {
+                if ("1.2.3.4".equals(ip))
+                    unsaturated.countDown();
+            }
+        });
+
+        AtomicReference<AsyncContext> asyncRef = new AtomicReference<>();
+        CountDownLatch asyncLatch = new CountDownLatch(1);
+        AtomicInteger handled = new AtomicInteger();
+        handler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                handled.incrementAndGet();
+                if ("/async".equals(target))
+                {
+                    asyncRef.set(request.startAsync());
+                    asyncLatch.countDown();
+                }
+            }
+        });
+        _server.setHandler(handler);
+        _server.start();
+
+        LocalConnector.LocalEndPoint asyncEndPoint = _local.executeRequest("GET /async HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n");
+        assertTrue(asyncLatch.await(5, TimeUnit.SECONDS));
+
+        // The async request holds the permit, so this request is queued.
+        LocalConnector.LocalEndPoint queuedEndPoint = _local.executeRequest("GET /queued HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n");
+        assertTrue(saturated.await(5, TimeUnit.SECONDS));
+        assertThat(handled.get(), is(1));
+
+        // Other remote addresses are not affected.
+        assertThat(_local.getResponse("GET /other HTTP/1.0\r\nForwarded: for=6.6.6.6\r\n\r\n"), containsString(" 200 OK"));
+
+        // Completing the async request releases the permit.
+        asyncRef.get().complete();
+        assertThat(asyncEndPoint.getResponse(), containsString(" 200 OK"));
+        assertThat(queuedEndPoint.getResponse(), containsString(" 200 OK"));
+        assertTrue(unsaturated.await(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testMaxQueueSize() throws Exception
+    {
+        ThreadLimitHandler handler = new ThreadLimitHandler("Forwarded");
+        handler.setThreadLimit(1);
+        handler.setMaxQueueSize(0);
+        CountDownLatch rejected = new CountDownLatch(1);
+        handler.addListener(new ThreadLimitHandler.Listener()
+        {
+            @Override
+            public void onRejected(ThreadLimitHandler handler, String ip)
+            {
+                rejected.countDown();
+            }
+        });
+
+        AtomicReference<AsyncContext> asyncRef = new AtomicReference<>();
+        CountDownLatch asyncLatch = new CountDownLatch(1);
+        handler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                if ("/async".equals(target))
+                {
+                    asyncRef.set(request.startAsync());
+                    asyncLatch.countDown();
+                }
+            }
+        });
+        _server.setHandler(handler);
+        _server.start();
+
+        LocalConnector.LocalEndPoint asyncEndPoint = _local.executeRequest("GET /async HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n");
+        assertTrue(asyncLatch.await(5, TimeUnit.SECONDS));
+
+        assertThat(_local.getResponse("GET /rejected HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n"), containsString(" 429 "));
+        assertTrue(rejected.await(5, TimeUnit.SECONDS));
+
+        asyncRef.get().complete();
+        assertThat(asyncEndPoint.getResponse(), containsString(" 200 OK"));
+        assertThat(_local.getResponse("GET / HTTP/1.0\r\nForwarded: for=1.2.3.4\r\n\r\n"), containsString(" 200 OK"));
+    }
 }
@@ -0,0 +1,196 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class UsageAccountingHandlerTest
+{
+    private static final long PERIOD = TimeUnit.HOURS.toMillis(1);
+
+    private final AtomicLong _now = new AtomicLong(PERIOD * 1000);
+    private Server _server;
+    private LocalConnector _connector;
+    private UsageAccountingHandler _accountingHandler;
+    private UsageQuotaHandler _quotaHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _quotaHandler = new UsageQuotaHandler();
+        _accountingHandler = new UsageAccountingHandler()
+        {
+            @Override
+            protected long currentTimeMillis()
+            {
+                return _now.get();
+            }
+        };
+        _accountingHandler.setKeyExtractor(UsageAccountingHandler.header("X-API-Key"));
+        _accountingHandler.setPeriod(PERIOD);
+        _accountingHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                IO.readBytes(request.getInputStream());
+                response.setStatus(HttpStatus.OK_200);
+                byte[] content = "0123456789".getBytes(StandardCharsets.US_ASCII);
+                response.setContentLength(content.length);
+                response.getOutputStream().write(content);
+            }
+        });
+        _quotaHandler.setHandler(_accountingHandler);
+        _server.setHandler(_quotaHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response post(String key, String content) throws Exception
+    {
+        String request = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            (key == null ? "" : "X-API-Key: " + key + "\r\n") +
+            "Content-Length: " + content.length() + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            content;
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testAccountsHeadersAndContent() throws Exception
+    {
+        _server.start();
+
+        assertThat(post("a", "").getStatus(), is(HttpStatus.OK_200));
+        UsageAccountingHandler.Usage usage = _accountingHandler.getUsage("a");
+        assertThat(usage, notNullValue());
+        long headersReceived = usage.getBytesReceived();
+        long sent = usage.getBytesSent();
+        // The response status line and headers are accounted along with the content.
+        assertThat(sent, greaterThan(10L + "HTTP/1.1 200 OK\r\n".length()));
+
+        String content = "x".repeat(1000);
+        assertThat(post("a", content).getStatus(), is(HttpStatus.OK_200));
+        assertThat(usage.getRequests(), is(2L));
+        // The Content-Length header of the second request is 3 digits longer.
+        assertThat(usage.getBytesReceived(), is(2 * headersReceived + content.length() + 3));
+        assertThat(usage.getBytesSent(), is(2 * sent));
+
+        assertThat(post("b", "").getStatus(), is(HttpStatus.OK_200));
+        assertThat(_accountingHandler.getUsage("b").getRequests(), is(1L));
+
+        // Requests without key are not accounted.
+        assertThat(post(null, "").getStatus(), is(HttpStatus.OK_200));
+        assertThat(_accountingHandler.getKeys(), is(2));
+    }
+
+    @Test
+    public void testMaxKeys() throws Exception
+    {
+        _accountingHandler.setMaxKeys(1);
+        _server.start();
+
+        post("a", "");
+        post("b", "");
+        post("c", "");
+
+        assertThat(_accountingHandler.getUsage("a").getRequests(), is(1L));
+        assertThat(_accountingHandler.getUsage("b"), nullValue());
+        assertThat(_accountingHandler.getUsage(UsageAccountingHandler.OTHER_KEY).getRequests(), is(2L));
+    }
+
+    @Test
+    public void testRollup() throws Exception
+    {
+        _server.start();
+
+        post("a", "hello");
+        post("a", "hello");
+        UsageAccountingHandler.Usage usage = _accountingHandler.getUsage("a");
+        assertThat(usage.getLastRollup(), nullValue());
+        UsageAccountingHandler.Rollup current = usage.getCurrentPeriod();
+        assertThat(current.getRequests(), is(2L));
+        assertThat(current.getStart(), is(_now.get()));
+        assertThat(current.getEnd(), is(_now.get() + PERIOD));
+
+        _now.addAndGet(PERIOD + 1);
+        UsageAccountingHandler.Rollup rollup = usage.getLastRollup();
+        assertThat(rollup.getRequests(), is(2L));
+        assertThat(rollup.getBytes(), is(current.getBytes()));
+        assertThat(usage.getCurrentPeriod().getRequests(), is(0L));
+
+        // Periods without traffic roll up as empty.
+        _now.addAndGet(2 * PERIOD);
+        rollup = usage.getLastRollup();
+        assertThat(rollup.getRequests(), is(0L));
+        assertThat(rollup.getEnd(), is(usage.getCurrentPeriod().getStart()));
+        // The totals are not affected.
+        assertThat(usage.getRequests(), is(2L));
+    }
+
+    @Test
+    public void testQuota() throws Exception
+    {
+        _quotaHandler.setDefaultQuota(1);
+        _quotaHandler.setQuota("vip", -1);
+        _server.start();
+
+        assertThat(post("a", "").getStatus(), is(HttpStatus.OK_200));
+        HttpTester.Response response = post("a", "");
+        assertThat(response.getStatus(), is(HttpStatus.TOO_MANY_REQUESTS_429));
+        assertThat(response.get(HttpHeader.RETRY_AFTER), is(String.valueOf(TimeUnit.MILLISECONDS.toSeconds(PERIOD))));
+        assertThat(_quotaHandler.getRequestsRejected(), is(1L));
+        // Rejected requests are not accounted.
+        assertThat(_accountingHandler.getUsage("a").getRequests(), is(1L));
+
+        assertThat(post("vip", "").getStatus(), is(HttpStatus.OK_200));
+        assertThat(post("vip", "").getStatus(), is(HttpStatus.OK_200));
+
+        // The quota is restored in the next period.
+        _now.addAndGet(PERIOD);
+        assertThat(post("a", "").getStatus(), is(HttpStatus.OK_200));
+    }
+}
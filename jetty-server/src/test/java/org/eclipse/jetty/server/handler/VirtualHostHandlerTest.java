@@ -0,0 +1,223 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.List;
+import java.util.stream.Collectors;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.ssl.SniX509ExtendedKeyManager;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.eclipse.jetty.util.ssl.X509;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class VirtualHostHandlerTest
+{
+    private Server server;
+    private LocalConnector connector;
+    private VirtualHostHandler virtualHostHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        virtualHostHandler = new VirtualHostHandler()
+        {
+            @Override
+            protected String getSniHost(Request baseRequest)
+            {
+                // Simulate the SNI sent by the client during the TLS handshake.
+                return baseRequest.getHeader("X-Test-SNI");
+            }
+        };
+        server.setHandler(virtualHostHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private static AbstractHandler reply(String name)
+    {
+        return new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setContentType("text/plain");
+                response.getWriter().print(name);
+            }
+        };
+    }
+
+    private HttpTester.Response request(String host, String sniHost) throws Exception
+    {
+        String request = "GET / HTTP/1.1\r\n" +
+            "Host: " + host + "\r\n" +
+            (sniHost == null ? "" : "X-Test-SNI: " + sniHost + "\r\n") +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testRouting() throws Exception
+    {
+        virtualHostHandler.addVirtualHost(reply("exact"), "www.example.com", "example.com");
+        virtualHostHandler.addVirtualHost(reply("wild"), "*.example.com");
+        virtualHostHandler.addVirtualHost(reply("regex"), "^tenant-[0-9]+\\.example\\.org$");
+        server.start();
+
+        assertThat(request("www.example.com", null).getContent(), is("exact"));
+        assertThat(request("EXAMPLE.com.", null).getContent(), is("exact"));
+        assertThat(request("www.example.com:8080", null).getContent(), is("exact"));
+        assertThat(request("api.example.com", null).getContent(), is("wild"));
+        assertThat(request("tenant-42.example.org", null).getContent(), is("regex"));
+
+        // Wildcards match a single label.
+        assertThat(request("a.b.example.com", null).getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(request("tenant-x.example.org", null).getStatus(), is(HttpStatus.NOT_FOUND_404));
+    }
+
+    @Test
+    public void testMatch()
+    {
+        VirtualHostHandler.VirtualHost wild = virtualHostHandler.addVirtualHost(reply("wild"), "*.example.com");
+        VirtualHostHandler.VirtualHost regex = virtualHostHandler.addVirtualHost(reply("regex"), "^.*\\.example\\.com$");
+        VirtualHostHandler.VirtualHost exact = virtualHostHandler.addVirtualHost(reply("exact"), "www.example.com");
+
+        // Exact patterns take precedence over wildcards, which take precedence over regular expressions.
+        assertThat(virtualHostHandler.match("www.example.com"), sameInstance(exact));
+        assertThat(virtualHostHandler.match("api.example.com"), sameInstance(wild));
+        assertThat(virtualHostHandler.match("a.b.example.com"), sameInstance(regex));
+        assertThat(virtualHostHandler.match("example.org"), nullValue());
+        assertThat(virtualHostHandler.match(null), nullValue());
+
+        assertThat(virtualHostHandler.removeVirtualHost(exact), is(true));
+        assertThat(virtualHostHandler.match("www.example.com"), sameInstance(wild));
+        assertThat(virtualHostHandler.getVirtualHosts(), is(List.of(wild, regex)));
+    }
+
+    @Test
+    public void testInvalidHostPatterns()
+    {
+        virtualHostHandler.addVirtualHost(reply("one"), "www.example.com");
+
+        assertThrows(IllegalArgumentException.class, () -> virtualHostHandler.addVirtualHost(reply("two"), "WWW.example.com"));
+        assertThrows(IllegalArgumentException.class, () -> virtualHostHandler.addVirtualHost(reply("two"), "www.*.com"));
+        assertThrows(IllegalArgumentException.class, () -> virtualHostHandler.addVirtualHost(reply("two"), "*."));
+        assertThrows(IllegalArgumentException.class, () -> virtualHostHandler.addVirtualHost(reply("two")));
+        // The failed additions left the virtual hosts unchanged.
+        assertThat(virtualHostHandler.getVirtualHosts().size(), is(1));
+    }
+
+    @Test
+    public void testSniPolicyVirtualHost() throws Exception
+    {
+        virtualHostHandler.addVirtualHost(reply("one"), "*.one.com");
+        virtualHostHandler.addVirtualHost(reply("two"), "*.two.com");
+        server.start();
+
+        // No SNI, for example a clear-text request.
+        assertThat(request("www.one.com", null).getContent(), is("one"));
+        // SNI for a different host of the same virtual host.
+        assertThat(request("www.one.com", "api.one.com").getContent(), is("one"));
+        // SNI for a different virtual host.
+        assertThat(request("www.two.com", "www.one.com").getStatus(), is(HttpStatus.MISDIRECTED_REQUEST_421));
+    }
+
+    @Test
+    public void testSniPolicyExact() throws Exception
+    {
+        virtualHostHandler.setSniPolicy(VirtualHostHandler.SniPolicy.EXACT);
+        virtualHostHandler.addVirtualHost(reply("one"), "*.one.com");
+        server.start();
+
+        assertThat(request("www.one.com", "WWW.one.com").getContent(), is("one"));
+        assertThat(request("www.one.com", "api.one.com").getStatus(), is(HttpStatus.MISDIRECTED_REQUEST_421));
+
+        virtualHostHandler.setSniPolicy(VirtualHostHandler.SniPolicy.IGNORE);
+        assertThat(request("www.one.com", "api.one.com").getContent(), is("one"));
+    }
+
+    @Test
+    public void testSniSelect() throws Exception
+    {
+        SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStorePath("src/test/resources/keystore_sni.p12");
+        sslContextFactory.setKeyStorePassword("storepwd");
+        sslContextFactory.setSNISelector(virtualHostHandler);
+        sslContextFactory.start();
+        try
+        {
+            List<X509> certificates = sslContextFactory.getAliases().stream()
+                .map(sslContextFactory::getX509)
+                .collect(Collectors.toList());
+            String wildAlias = alias(certificates, "www.domain.com");
+            String exampleAlias = alias(certificates, "www.example.com");
+
+            virtualHostHandler.addVirtualHost(reply("domain"), "*.domain.com");
+            VirtualHostHandler.VirtualHost tenants = virtualHostHandler.addVirtualHost(reply("tenants"), "^tenant-[0-9]+\\.example\\.org$");
+            tenants.setCertificateAliases(wildAlias);
+            VirtualHostHandler.VirtualHost example = virtualHostHandler.addVirtualHost(reply("example"), "www.example.com");
+            example.setCertificateAliases("missing");
+
+            // Virtual host without certificates configured, select the matching certificate.
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, "api.domain.com", certificates), is(wildAlias));
+            // Virtual host with certificates configured, select them even if they do not match the SNI.
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, "tenant-1.example.org", certificates), is(wildAlias));
+            // Configured certificates not available for this key type.
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, "www.example.com", certificates), nullValue());
+            // No virtual host matches, select among all the certificates.
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, "www.unknown.com", certificates), is(SniX509ExtendedKeyManager.SniSelector.DELEGATE));
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, null, certificates), is(SniX509ExtendedKeyManager.SniSelector.DELEGATE));
+
+            example.setCertificateAliases("missing", exampleAlias);
+            assertThat(virtualHostHandler.sniSelect("RSA", null, null, "www.example.com", certificates), is(exampleAlias));
+        }
+        finally
+        {
+            sslContextFactory.stop();
+        }
+    }
+
+    private static String alias(List<X509> certificates, String host)
+    {
+        return certificates.stream()
+            .filter(x509 -> x509.matches(host))
+            .map(X509::getAlias)
+            .findFirst()
+            .orElseThrow();
+    }
+}
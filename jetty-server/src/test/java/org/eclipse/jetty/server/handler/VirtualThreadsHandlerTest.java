@@ -0,0 +1,121 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler;
+
+import java.io.IOException;
+import java.util.concurrent.Executor;
+import javax.servlet.AsyncContext;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class VirtualThreadsHandlerTest
+{
+    private static final String THREAD_NAME = "test-virtual";
+
+    private Server _server;
+    private LocalConnector _connector;
+    private VirtualThreadsHandler _virtualThreadsHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _virtualThreadsHandler = new VirtualThreadsHandler()
+        {
+            @Override
+            public Executor getVirtualThreadsExecutor()
+            {
+                // Simulates virtual threads with named platform threads.
+                return task -> new Thread(task, THREAD_NAME).start();
+            }
+        };
+        _server.setHandler(_virtualThreadsHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String uri) throws Exception
+    {
+        String request = "GET " + uri + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testRequestHandledInVirtualThread() throws Exception
+    {
+        _virtualThreadsHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+                response.getWriter().print(request.getDispatcherType() + " " + target + " " + Thread.currentThread().getName());
+            }
+        });
+        _server.start();
+
+        HttpTester.Response response = get("/path");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("REQUEST /path " + THREAD_NAME));
+        assertThat(_virtualThreadsHandler.getRequestsRedispatched(), is(1L));
+    }
+
+    @Test
+    public void testAsyncDispatchInVirtualThread() throws Exception
+    {
+        _virtualThreadsHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                if (request.getAttribute("async") == null)
+                {
+                    request.setAttribute("async", Thread.currentThread().getName());
+                    AsyncContext asyncContext = request.startAsync();
+                    // Dispatch from a platform thread.
+                    new Thread(asyncContext::dispatch).start();
+                    return;
+                }
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+                response.getWriter().print(request.getDispatcherType() + " " + Thread.currentThread().getName());
+            }
+        });
+        _server.start();
+
+        HttpTester.Response response = get("/path");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("ASYNC " + THREAD_NAME));
+        assertThat(_virtualThreadsHandler.getRequestsRedispatched(), is(1L));
+    }
+}
@@ -0,0 +1,131 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.io.ByteArrayInputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.nio.charset.StandardCharsets;
+import java.util.zip.GZIPInputStream;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class CompressionHandlerTest
+{
+    private static final String CONTENT = "The quick brown fox jumps over the lazy dog. ".repeat(64);
+
+    private Server _server;
+    private LocalConnector _connector;
+    private CompressionHandler _compressionHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _compressionHandler = new CompressionHandler();
+        _compressionHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                response.setStatus(HttpStatus.OK_200);
+                response.setContentType(request.getParameter("type") == null ? "text/plain" : request.getParameter("type"));
+                response.setHeader(HttpHeader.ETAG.asString(), "\"tag\"");
+                response.getOutputStream().write(CONTENT.getBytes(StandardCharsets.UTF_8));
+            }
+        });
+        _server.setHandler(_compressionHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String uri, String headers) throws Exception
+    {
+        String request = "GET " + uri + " HTTP/1.1\r\nHost: localhost\r\n" + headers + "Connection: close\r\n\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    @Test
+    public void testGzipCompression() throws Exception
+    {
+        _compressionHandler.setLevel("gzip", 9);
+        _server.start();
+
+        HttpTester.Response response = get("/", "Accept-Encoding: deflate, gzip;q=0.8\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+        assertThat(response.get(HttpHeader.VARY), containsString("Accept-Encoding"));
+        assertThat(response.get(HttpHeader.ETAG), is("\"tag--gzip\""));
+        try (InputStream input = new GZIPInputStream(new ByteArrayInputStream(response.getContentBytes())))
+        {
+            assertThat(new String(input.readAllBytes(), StandardCharsets.UTF_8), is(CONTENT));
+        }
+    }
+
+    @Test
+    public void testNotAcceptedEncoding() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = get("/", "Accept-Encoding: gzip;q=0, identity\r\n");
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.get(HttpHeader.VARY), containsString("Accept-Encoding"));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testMimeTypeAllowList() throws Exception
+    {
+        _compressionHandler.setIncludedMimeTypes("text/html");
+        _server.start();
+
+        HttpTester.Response response = get("/", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), nullValue());
+        assertThat(response.getContent(), is(CONTENT));
+
+        response = get("/?type=text/html;charset=utf-8", "Accept-Encoding: gzip\r\n");
+        assertThat(response.get(HttpHeader.CONTENT_ENCODING), is("gzip"));
+    }
+
+    @Test
+    public void testUnknownEncodingIsRejected()
+    {
+        assertThrows(IllegalArgumentException.class, () -> _compressionHandler.setEncodings("unknown"));
+    }
+}
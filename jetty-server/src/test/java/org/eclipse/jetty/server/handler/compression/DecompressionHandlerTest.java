@@ -0,0 +1,212 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.handler.compression;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.zip.Deflater;
+import java.util.zip.DeflaterOutputStream;
+import java.util.zip.GZIPOutputStream;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class DecompressionHandlerTest
+{
+    private static final String CONTENT = "The quick brown fox jumps over the lazy dog. ".repeat(64);
+
+    private Server _server;
+    private LocalConnector _connector;
+    private DecompressionHandler _decompressionHandler;
+
+    @BeforeEach
+    public void before()
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _decompressionHandler = new DecompressionHandler();
+        _decompressionHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                byte[] content = IO.readBytes(request.getInputStream());
+                response.setHeader("X-Request-Content-Encoding", String.valueOf(request.getHeader(HttpHeader.CONTENT_ENCODING.asString())));
+                response.setHeader("X-Original-Content-Encoding", String.valueOf(request.getHeader("X-Content-Encoding")));
+                response.getOutputStream().write(content);
+            }
+        });
+        _server.setHandler(_decompressionHandler);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response post(String contentEncoding, byte[] content) throws Exception
+    {
+        String headers = "POST / HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Encoding: " + contentEncoding + "\r\n" +
+            "Content-Length: " + content.length + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n";
+        ByteBuffer request = BufferUtil.allocate(headers.length() + content.length);
+        BufferUtil.append(request, headers.getBytes(StandardCharsets.ISO_8859_1));
+        BufferUtil.append(request, content);
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    private static byte[] gzip(byte[] content) throws IOException
+    {
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+        try (OutputStream output = new GZIPOutputStream(bytes))
+        {
+            output.write(content);
+        }
+        return bytes.toByteArray();
+    }
+
+    private static byte[] deflate(byte[] content, boolean raw) throws IOException
+    {
+        ByteArrayOutputStream bytes = new ByteArrayOutputStream();
+        try (OutputStream output = new DeflaterOutputStream(bytes, new Deflater(Deflater.DEFAULT_COMPRESSION, raw)))
+        {
+            output.write(content);
+        }
+        return bytes.toByteArray();
+    }
+
+    @Test
+    public void testGzipRequestContent() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = post("gzip", gzip(CONTENT.getBytes(StandardCharsets.UTF_8)));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+        assertThat(response.get("X-Request-Content-Encoding"), is("null"));
+        assertThat(response.get("X-Original-Content-Encoding"), is("gzip"));
+        assertThat(_decompressionHandler.getDecompressedRequests(), is(1L));
+    }
+
+    @Test
+    public void testDeflateRequestContent() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = post("deflate", deflate(CONTENT.getBytes(StandardCharsets.UTF_8), false));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+
+        // Raw deflate content without the zlib header.
+        response = post("deflate", deflate(CONTENT.getBytes(StandardCharsets.UTF_8), true));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testMultipleContentCodings() throws Exception
+    {
+        _server.start();
+
+        byte[] content = gzip(deflate(CONTENT.getBytes(StandardCharsets.UTF_8), false));
+        HttpTester.Response response = post("deflate, gzip", content);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(CONTENT));
+    }
+
+    @Test
+    public void testUnsupportedContentCoding() throws Exception
+    {
+        _decompressionHandler.setEncodings("gzip");
+        _server.start();
+
+        HttpTester.Response response = post("deflate", deflate(CONTENT.getBytes(StandardCharsets.UTF_8), false));
+        assertThat(response.getStatus(), is(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415));
+        assertThat(response.get(HttpHeader.ACCEPT_ENCODING), containsString("gzip"));
+        assertThat(_decompressionHandler.getUnsupportedRequests(), is(1L));
+
+        response = post("br", new byte[16]);
+        assertThat(response.getStatus(), is(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415));
+    }
+
+    @Test
+    public void testMaxDecompressedSize() throws Exception
+    {
+        _decompressionHandler.setMaxDecompressedSize(1024);
+        _server.start();
+
+        // A highly compressible content that expands well beyond the limit.
+        HttpTester.Response response = post("gzip", gzip(new byte[1024 * 1024]));
+        assertThat(response.getStatus(), is(HttpStatus.PAYLOAD_TOO_LARGE_413));
+        assertThat(_decompressionHandler.getOversizedRequests(), is(1L));
+
+        response = post("gzip", gzip(new byte[512]));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentBytes().length, is(512));
+    }
+
+    @Test
+    public void testInvalidRequestContent() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = post("deflate", "not deflated content".getBytes(StandardCharsets.UTF_8));
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+    }
+
+    @Test
+    public void testNoContentEncoding() throws Exception
+    {
+        _server.start();
+
+        HttpTester.Response response = HttpTester.parseResponse(_connector.getResponse(
+            "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\nConnection: close\r\n\r\nhello"));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("hello"));
+        assertThat(response.get("X-Original-Content-Encoding"), is("null"));
+        assertThat(_decompressionHandler.getDecompressedRequests(), is(0L));
+    }
+
+    @Test
+    public void testUnknownEncodingIsRejected()
+    {
+        assertThrows(IllegalArgumentException.class, () -> _decompressionHandler.setEncodings("unknown"));
+    }
+}
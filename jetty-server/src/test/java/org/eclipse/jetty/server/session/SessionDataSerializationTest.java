@@ -0,0 +1,142 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.ObjectOutputStream;
+import java.nio.charset.StandardCharsets;
+import java.util.Map;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class SessionDataSerializationTest
+{
+    private static SessionData newSessionData(String id, String value)
+    {
+        SessionData data = new SessionData(id, "/ctx", "0.0.0.0", 100, 200, 200, -1);
+        data.setAttribute("value", value);
+        data.setAttribute("number", 42);
+        return data;
+    }
+
+    private static SessionData roundTrip(SessionDataSerialization serialization, byte[] bytes) throws IOException
+    {
+        SessionData copy = new SessionData("copy", "/ctx", "0.0.0.0", 100, 200, 200, -1);
+        serialization.deserializeAttributes(copy, bytes);
+        return copy;
+    }
+
+    @Test
+    public void testDefaultIsLegacyJavaSerialization() throws Exception
+    {
+        SessionDataSerialization serialization = new SessionDataSerialization();
+        SessionData data = newSessionData("1234", "foo");
+
+        byte[] bytes = serialization.serializeAttributes(data);
+
+        // Exactly the same bytes as the legacy serialization.
+        ByteArrayOutputStream legacy = new ByteArrayOutputStream();
+        try (ObjectOutputStream out = new ObjectOutputStream(legacy))
+        {
+            SessionData.serializeAttributes(data, out);
+        }
+        assertThat(bytes, is(legacy.toByteArray()));
+
+        SessionData copy = roundTrip(serialization, bytes);
+        assertThat(copy.getAttribute("value"), is("foo"));
+        assertThat(copy.getAttribute("number"), is(42));
+        assertThat(serialization.getSerializedCount(), is(1L));
+        assertThat(serialization.getSerializedSizeTotal(), is((long)bytes.length));
+        assertThat(serialization.getStoredSizeTotal(), is((long)bytes.length));
+        assertThat(serialization.getCompressedCount(), is(0L));
+    }
+
+    @Test
+    public void testCompression() throws Exception
+    {
+        SessionDataSerialization serialization = new SessionDataSerialization();
+        serialization.setCompression("gzip");
+        serialization.setCompressionThreshold(512);
+
+        // Small sessions are not compressed.
+        byte[] small = serialization.serializeAttributes(newSessionData("small", "foo"));
+        assertThat(small[0] & 0xFF, is(0xAC));
+        assertThat(serialization.getCompressedCount(), is(0L));
+
+        SessionData large = newSessionData("large", "x".repeat(16 * 1024));
+        byte[] bytes = serialization.serializeAttributes(large);
+        assertThat(serialization.getCompressedCount(), is(1L));
+        assertThat(serialization.getStoredSizeTotal(), lessThan(serialization.getSerializedSizeTotal()));
+        assertThat(serialization.getLargestSessionId(), is("large"));
+
+        // A store without compression can still read compressed sessions.
+        SessionData copy = roundTrip(new SessionDataSerialization(), bytes);
+        assertThat(copy.getAttribute("value"), is(large.getAttribute("value")));
+
+        serialization.reset();
+        assertThat(serialization.getSerializedCount(), is(0L));
+        assertThat(serialization.getLargestSessionId(), is((String)null));
+    }
+
+    @Test
+    public void testCustomCodec() throws Exception
+    {
+        SessionDataCodec codec = new SessionDataCodec("text")
+        {
+            @Override
+            public byte[] encode(SessionData data)
+            {
+                return String.valueOf(data.getAttribute("value")).getBytes(StandardCharsets.UTF_8);
+            }
+
+            @Override
+            public void decode(SessionData data, byte[] bytes)
+            {
+                data.putAllAttributes(Map.of("value", new String(bytes, StandardCharsets.UTF_8)));
+            }
+        };
+        SessionDataSerialization serialization = new SessionDataSerialization();
+        serialization.setCodec(codec);
+
+        byte[] bytes = serialization.serializeAttributes(newSessionData("1234", "bar"));
+        SessionData copy = roundTrip(serialization, bytes);
+        assertThat(copy.getAttribute("value"), is("bar"));
+
+        // The codec name is recorded, so a store without the codec fails.
+        assertThrows(IOException.class, () -> roundTrip(new SessionDataSerialization(), bytes));
+    }
+
+    @Test
+    public void testUnknownCodecAndCompressionNames()
+    {
+        SessionDataSerialization serialization = new SessionDataSerialization();
+        assertThrows(IllegalArgumentException.class, () -> serialization.setCodecName("unknown"));
+        assertThrows(IllegalArgumentException.class, () -> serialization.setCompression("unknown"));
+        serialization.setCodecName(null);
+        assertThat(serialization.getCodecName(), is("java"));
+    }
+
+    @Test
+    public void testUnsupportedVersion()
+    {
+        byte[] bytes = {0x4A, 0x53, (byte)(SessionDataSerialization.VERSION + 1), 0, 0, 0, 0, 0, 0, 0, 0};
+        assertThrows(IOException.class, () -> roundTrip(new SessionDataSerialization(), bytes));
+    }
+}
@@ -0,0 +1,194 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.io.File;
+import java.util.Set;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class SessionEventBroadcasterTest
+{
+    private final LinkedBroadcaster _local = new LinkedBroadcaster();
+    private final LinkedBroadcaster _remote = new LinkedBroadcaster();
+    private Server _server;
+    private Server _otherServer;
+    public WorkDir workDir;
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (_server != null)
+            _server.stop();
+        if (_otherServer != null)
+            _otherServer.stop();
+        _local.stop();
+        _remote.stop();
+    }
+
+    private void link() throws Exception
+    {
+        _local._peer = _remote;
+        _remote._peer = _local;
+        _local.start();
+        _remote.start();
+    }
+
+    @Test
+    public void testEventsAreDeliveredToPeersOnly() throws Exception
+    {
+        link();
+        BlockingQueue<SessionEvent> localEvents = new LinkedBlockingQueue<>();
+        BlockingQueue<SessionEvent> remoteEvents = new LinkedBlockingQueue<>();
+        _local.addListener(localEvents::offer);
+        _remote.addListener(remoteEvents::offer);
+
+        _local.publish(new SessionEvent(SessionEvent.Type.ATTRIBUTES_CHANGED, "node1", "0.0.0.0", "ctx", "1234", Set.of("a", "b")));
+
+        SessionEvent event = remoteEvents.poll(5, TimeUnit.SECONDS);
+        assertThat(event, notNullValue());
+        assertThat(event.getType(), is(SessionEvent.Type.ATTRIBUTES_CHANGED));
+        assertThat(event.getWorkerName(), is("node1"));
+        assertThat(event.getVhost(), is("0.0.0.0"));
+        assertThat(event.getCanonicalContextPath(), is("ctx"));
+        assertThat(event.getId(), is("1234"));
+        assertThat(event.getAttributeNames(), is(Set.of("a", "b")));
+        // The event looped back to the publisher is discarded.
+        assertThat(localEvents.poll(), nullValue());
+        assertThat(_local.getEventsPublished(), is(1L));
+        assertThat(_remote.getEventsReceived(), is(1L));
+    }
+
+    @Test
+    public void testRemoteInvalidationEvictsCachedSession() throws Exception
+    {
+        link();
+        _server = new Server();
+        SessionHandler sessionHandler = new SessionHandler();
+        sessionHandler.setSessionEventBroadcaster(_local);
+        _server.setHandler(sessionHandler);
+        _server.start();
+
+        BlockingQueue<SessionEvent> remoteEvents = new LinkedBlockingQueue<>();
+        _remote.addListener(remoteEvents::offer);
+
+        // A session in use is not evicted.
+        SessionCache cache = sessionHandler.getSessionCache();
+        Session session = cache.newSession(null, "1234", System.currentTimeMillis(), -1);
+        cache.add("1234", session);
+        SessionContext context = sessionHandler._sessionContext;
+        SessionEvent invalidated = new SessionEvent(SessionEvent.Type.INVALIDATED, null, context.getVhost(), context.getCanonicalContextPath(), "1234", null);
+        _remote.publish(invalidated);
+        assertThat(cache.contains("1234"), is(true));
+
+        // Once released, it is evicted.
+        cache.release("1234", session);
+        _remote.publish(invalidated);
+        assertThat(cache.contains("1234"), is(false));
+
+        // Events for other contexts are ignored.
+        session = cache.newSession(null, "5678", System.currentTimeMillis(), -1);
+        cache.add("5678", session);
+        cache.release("5678", session);
+        _remote.publish(new SessionEvent(SessionEvent.Type.INVALIDATED, null, context.getVhost(), "other", "5678", null));
+        assertThat(cache.contains("5678"), is(true));
+
+        // Local invalidation is published.
+        sessionHandler.invalidate("5678");
+        SessionEvent event = remoteEvents.poll(5, TimeUnit.SECONDS);
+        assertThat(event, notNullValue());
+        assertThat(event.getType(), is(SessionEvent.Type.INVALIDATED));
+        assertThat(event.getId(), is("5678"));
+        assertThat(event.isFor(context), is(true));
+    }
+
+    @Test
+    public void testDrainHandsOffSessions() throws Exception
+    {
+        link();
+        File storeDir = workDir.getEmptyPathDir().toFile();
+
+        _server = new Server();
+        SessionHandler sessionHandler = newSessionHandler(_local, storeDir);
+        sessionHandler.setHandoffOnDrain(true);
+        _server.setHandler(sessionHandler);
+        _server.start();
+
+        _otherServer = new Server();
+        SessionHandler otherSessionHandler = newSessionHandler(_remote, storeDir);
+        _otherServer.setHandler(otherSessionHandler);
+        _otherServer.start();
+
+        SessionCache cache = sessionHandler.getSessionCache();
+        Session session = cache.newSession(null, "1234", System.currentTimeMillis(), -1);
+        cache.add("1234", session);
+        session.setAttribute("name", "value");
+
+        // A session in use is not drained.
+        assertThat(sessionHandler.drain(100, TimeUnit.MILLISECONDS), is(0));
+        assertThat(cache.contains("1234"), is(true));
+
+        // Once released, it is stored, removed from the cache and handed off.
+        cache.release("1234", session);
+        assertThat(sessionHandler.drain(5, TimeUnit.SECONDS), is(1));
+        assertThat(cache.contains("1234"), is(false));
+        assertThat(sessionHandler.getSessionCache().getSessionDataStore().exists("1234"), is(true));
+
+        SessionCache otherCache = otherSessionHandler.getSessionCache();
+        await().atMost(5, TimeUnit.SECONDS).until(() -> otherCache.contains("1234"));
+        Session handedOff = otherCache.get("1234");
+        assertThat(handedOff.getAttribute("name"), is("value"));
+        otherCache.release("1234", handedOff);
+    }
+
+    private static SessionHandler newSessionHandler(SessionEventBroadcaster broadcaster, File storeDir)
+    {
+        SessionHandler sessionHandler = new SessionHandler();
+        sessionHandler.setSessionEventBroadcaster(broadcaster);
+        DefaultSessionCache cache = new DefaultSessionCache(sessionHandler);
+        FileSessionDataStore store = new FileSessionDataStore();
+        store.setStoreDir(storeDir);
+        cache.setSessionDataStore(store);
+        sessionHandler.setSessionCache(cache);
+        return sessionHandler;
+    }
+
+    private static class LinkedBroadcaster extends AbstractSessionEventBroadcaster
+    {
+        private LinkedBroadcaster _peer;
+
+        @Override
+        protected void send(byte[] bytes)
+        {
+            // Like multicast, deliver to ourselves too.
+            receive(bytes);
+            _peer.receive(bytes);
+        }
+    }
+}
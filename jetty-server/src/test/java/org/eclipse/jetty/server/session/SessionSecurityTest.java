@@ -0,0 +1,196 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.session;
+
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+import javax.servlet.http.HttpSession;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class SessionSecurityTest
+{
+    private final List<String> _events = new CopyOnWriteArrayList<>();
+    private Server _server;
+    private LocalConnector _connector;
+    private SessionHandler _sessionHandler;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        _server = new Server();
+        _connector = new LocalConnector(_server);
+        _server.addConnector(_connector);
+        _sessionHandler = new SessionHandler();
+        _sessionHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                switch (target)
+                {
+                    case "/create":
+                        request.getSession(true);
+                        break;
+                    case "/login":
+                        // Simulates what the authenticators of jetty-security do.
+                        String user = request.getParameter("user");
+                        _sessionHandler.renewSessionOnAuthentication(request, response);
+                        if (!_sessionHandler.registerAuthenticatedSession(request, () -> user))
+                            response.setStatus(HttpStatus.FORBIDDEN_403);
+                        break;
+                    case "/check":
+                        HttpSession session = request.getSession(false);
+                        response.setStatus(session == null ? HttpStatus.NOT_FOUND_404 : HttpStatus.OK_200);
+                        break;
+                    default:
+                        response.setStatus(HttpStatus.BAD_REQUEST_400);
+                        break;
+                }
+            }
+        });
+        _sessionHandler.addEventListener(new SessionSecurityListener()
+        {
+            @Override
+            public void onSessionIdRenewed(HttpServletRequest request, Session session, String oldId)
+            {
+                _events.add("renewed");
+            }
+
+            @Override
+            public void onSessionRegistered(HttpServletRequest request, Session session, String userKey)
+            {
+                _events.add("registered " + userKey);
+            }
+
+            @Override
+            public void onSessionDenied(HttpServletRequest request, Session session, String userKey)
+            {
+                _events.add("denied " + userKey);
+            }
+
+            @Override
+            public void onSessionEvicted(HttpServletRequest request, String id, String userKey)
+            {
+                _events.add("evicted " + userKey);
+            }
+        });
+        _server.setHandler(_sessionHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        _server.stop();
+    }
+
+    private HttpTester.Response get(String uri, String sessionCookie) throws Exception
+    {
+        String request = "GET " + uri + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            (sessionCookie == null ? "" : "Cookie: " + sessionCookie + "\r\n") +
+            "Connection: close\r\n" +
+            "\r\n";
+        return HttpTester.parseResponse(_connector.getResponse(request));
+    }
+
+    private static String sessionCookie(HttpTester.Response response)
+    {
+        String setCookie = response.get(HttpHeader.SET_COOKIE);
+        assertThat(setCookie, notNullValue());
+        return setCookie.substring(0, setCookie.indexOf(';'));
+    }
+
+    private String newSession() throws Exception
+    {
+        return sessionCookie(get("/create", null));
+    }
+
+    @Test
+    public void testSessionIdRenewedOnAuthentication() throws Exception
+    {
+        _server.start();
+
+        String cookie = newSession();
+        HttpTester.Response response = get("/login?user=alice", cookie);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        String renewed = sessionCookie(response);
+        assertThat(renewed, not(is(cookie)));
+
+        // The old id cannot be used anymore.
+        assertThat(get("/check", cookie).getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(get("/check", renewed).getStatus(), is(HttpStatus.OK_200));
+
+        // The id is renewed only once.
+        response = get("/login?user=alice", renewed);
+        assertThat(response.get(HttpHeader.SET_COOKIE), nullValue());
+        assertThat(_events, contains("renewed"));
+    }
+
+    @Test
+    public void testInvalidateOldest() throws Exception
+    {
+        _sessionHandler.setMaxSessionsPerUser(1);
+        _server.start();
+
+        String first = sessionCookie(get("/login?user=alice", newSession()));
+        assertThat(_sessionHandler.getSessionsPerUser("alice"), is(1));
+        String second = sessionCookie(get("/login?user=alice", newSession()));
+        assertThat(_sessionHandler.getSessionsPerUser("alice"), is(1));
+
+        assertThat(get("/check", first).getStatus(), is(HttpStatus.NOT_FOUND_404));
+        assertThat(get("/check", second).getStatus(), is(HttpStatus.OK_200));
+        assertThat(_events, contains("renewed", "registered alice", "renewed", "evicted alice", "registered alice"));
+
+        // Invalidated sessions are unregistered.
+        _sessionHandler.getSessionIdManager().invalidateAll(second.substring(second.indexOf('=') + 1, second.indexOf('.')));
+        assertThat(_sessionHandler.getSessionsPerUser("alice"), is(0));
+    }
+
+    @Test
+    public void testDenyNew() throws Exception
+    {
+        _sessionHandler.setMaxSessionsPerUser(1);
+        _sessionHandler.setConcurrentSessionPolicy(SessionHandler.ConcurrentSessionPolicy.DENY_NEW);
+        _sessionHandler.setUserKeyExtractor((request, principal) -> "tenant/" + principal.getName());
+        _server.start();
+
+        String first = sessionCookie(get("/login?user=alice", newSession()));
+        assertThat(get("/login?user=alice", newSession()).getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(get("/login?user=bob", newSession()).getStatus(), is(HttpStatus.OK_200));
+
+        assertThat(get("/check", first).getStatus(), is(HttpStatus.OK_200));
+        assertThat(_sessionHandler.getSessionsPerUser("tenant/alice"), is(1));
+        assertThat(_events, contains("renewed", "registered tenant/alice", "renewed", "denied tenant/alice", "renewed", "registered tenant/bob"));
+    }
+}
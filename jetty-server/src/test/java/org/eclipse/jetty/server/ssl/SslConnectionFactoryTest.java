@@ -35,9 +35,11 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.http.HttpVersion;
 import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.io.ssl.TlsResumptionStatistics;
 import org.eclipse.jetty.server.HttpConfiguration;
 import org.eclipse.jetty.server.HttpConnectionFactory;
 import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.SecureRequestCustomizer;
 import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.ServerConnector;
 import org.eclipse.jetty.server.SocketCustomizationListener;
@@ -96,7 +98,9 @@ public class SslConnectionFactoryTest
             public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
             {
                 response.setStatus(200);
-                response.getWriter().write("url=" + request.getRequestURI() + "\nhost=" + request.getServerName());
+                response.getWriter().write("url=" + request.getRequestURI() + "\nhost=" + request.getServerName() +
+                    "\nja3=" + request.getAttribute(SecureRequestCustomizer.TLS_JA3) +
+                    "\nja4=" + request.getAttribute(SecureRequestCustomizer.TLS_JA4));
                 response.flushBuffer();
             }
         });
@@ -126,6 +130,52 @@ public class SslConnectionFactoryTest
         assertThat(response, Matchers.containsString("host=localhost"));
     }
 
+    @Test
+    public void testTlsFingerprinting() throws Exception
+    {
+        String response = getResponse("localhost", "localhost", "localhost");
+        assertThat(response, Matchers.containsString("ja4=null"));
+
+        _connector.getBean(SslConnectionFactory.class).setTlsFingerprinting(true);
+        response = getResponse("localhost", "localhost", "localhost");
+        // The JDK client sends SNI and offers TLS 1.3.
+        assertThat(response, Matchers.containsString("ja3=771,"));
+        assertThat(response, Matchers.containsString("ja4=t13d"));
+    }
+
+    @Test
+    public void testTlsResumptionStatistics() throws Exception
+    {
+        TlsResumptionStatistics statistics = new TlsResumptionStatistics();
+        _connector.addBean(statistics);
+
+        SslContextFactory.Client clientContextFactory = new SslContextFactory.Client(true);
+        // With TLS 1.2, the resumed session is the one created by the first handshake.
+        clientContextFactory.setIncludeProtocols("TLSv1.2");
+        clientContextFactory.start();
+        try
+        {
+            SSLSocketFactory factory = clientContextFactory.getSslContext().getSocketFactory();
+            for (int i = 0; i < 2; ++i)
+            {
+                try (SSLSocket sslSocket = (SSLSocket)factory.createSocket("127.0.0.1", _port))
+                {
+                    sslSocket.startHandshake();
+                    sslSocket.getOutputStream().write(("GET /ctx/path HTTP/1.0\r\nHost: localhost\r\n\r\n").getBytes(StandardCharsets.ISO_8859_1));
+                    assertThat(IO.toString(sslSocket.getInputStream()), Matchers.startsWith("HTTP/1.1 200 OK"));
+                }
+            }
+        }
+        finally
+        {
+            clientContextFactory.stop();
+        }
+
+        assertEquals(2, statistics.getHandshakes());
+        assertEquals(1, statistics.getResumedHandshakes());
+        assertEquals(0.5D, statistics.getResumptionRate());
+    }
+
     @Test
     public void testBadHandshake() throws Exception
     {
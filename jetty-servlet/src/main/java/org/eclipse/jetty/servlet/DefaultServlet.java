@@ -36,6 +36,7 @@ import org.eclipse.jetty.server.ResourceService;
 import org.eclipse.jetty.server.ResourceService.WelcomeFactory;
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.server.handler.ResourceHandler;
+import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.URIUtil;
 import org.eclipse.jetty.util.resource.Resource;
 import org.eclipse.jetty.util.resource.ResourceFactory;
@@ -116,8 +117,16 @@ import org.slf4j.LoggerFactory;
  *  otherGzipFileExtensions
  *                    Other file extensions that signify that a file is already compressed. Eg ".svgz"
  *
+ *  attachmentFileExtensions
+ *                    A comma separated list of file extensions, eg ".zip,.pdf", of the files sent
+ *                    with a Content-Disposition: attachment header carrying the file name, so that
+ *                    browsers save them rather than display them; "*" matches all the files.
+ *
  *  encodingHeaderCacheSize
  *                    Max entries in a cache of ACCEPT-ENCODING headers.
+ *
+ *  sendFileThreshold The min size of a file sent without copying it to user-space buffers,
+ *                    using FileChannel.transferTo, or -1 (the default) to disable zero-copy.
  * </pre>
  */
 public class DefaultServlet extends HttpServlet implements ResourceFactory, WelcomeFactory
@@ -228,6 +237,10 @@ public class DefaultServlet extends HttpServlet implements ResourceFactory, Welc
                 LOG.warn("Unable to use stylesheet: {} - {}", css, e.toString());
         }
 
+        String sendFileThreshold = getInitParameter("sendFileThreshold");
+        if (sendFileThreshold != null && sendFileThreshold.length() > 0)
+            _resourceService.setSendFileThreshold(Long.parseLong(sendFileThreshold));
+
         int encodingHeaderCacheSize = getInitInt("encodingHeaderCacheSize", -1);
         if (encodingHeaderCacheSize >= 0)
             _resourceService.setEncodingCacheSize(encodingHeaderCacheSize);
@@ -298,6 +311,19 @@ public class DefaultServlet extends HttpServlet implements ResourceFactory, Welc
         }
         _resourceService.setGzipEquivalentFileExtensions(gzipEquivalentFileExtensions);
 
+        String attachmentExtensions = getInitParameter("attachmentFileExtensions");
+        if (attachmentExtensions != null)
+        {
+            List<String> attachmentFileExtensions = new ArrayList<>();
+            for (String s : StringUtil.csvSplit(attachmentExtensions))
+            {
+                if (s.isEmpty())
+                    continue;
+                attachmentFileExtensions.add(s.charAt(0) == '.' || "*".equals(s) ? s : "." + s);
+            }
+            _resourceService.setAttachmentFileExtensions(attachmentFileExtensions);
+        }
+
         _servletHandler = _contextHandler.getChildHandlerByClass(ServletHandler.class);
 
         if (LOG.isDebugEnabled())
@@ -25,7 +25,11 @@ import java.util.List;
 import java.util.Map;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.Executor;
+import java.util.concurrent.RejectedExecutionException;
 import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
 import javax.servlet.DispatcherType;
 import javax.servlet.Filter;
 import javax.servlet.FilterRegistration;
@@ -64,6 +68,9 @@ import org.eclipse.jetty.server.handler.gzip.GzipHandler;
 import org.eclipse.jetty.server.session.SessionHandler;
 import org.eclipse.jetty.util.DecoratedObjectFactory;
 import org.eclipse.jetty.util.DeprecationWarning;
+import org.eclipse.jetty.util.MultiException;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.ProcessorUtils;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
@@ -95,6 +102,16 @@ public class ServletContextHandler extends ContextHandler
     public static final int SECURITY = 2;
     public static final int NO_SESSIONS = 0;
     public static final int NO_SECURITY = 0;
+    /**
+     * Context init parameter to enable the parallel initialization of the context.
+     * @see #setParallelInitialization(boolean)
+     */
+    public static final String PARALLEL_INITIALIZATION = "org.eclipse.jetty.servlet.parallelInitialization";
+    /**
+     * Context init parameter to enable the startup profiling of the context.
+     * @see #setStartupProfiling(boolean)
+     */
+    public static final String STARTUP_PROFILING = "org.eclipse.jetty.servlet.startupProfiling";
 
     public static ServletContextHandler getServletContextHandler(ServletContext servletContext, String purpose)
     {
@@ -120,6 +137,12 @@ public class ServletContextHandler extends ContextHandler
 
     public interface ServletContainerInitializerCaller extends LifeCycle {}
 
+    @FunctionalInterface
+    interface Initialization<T>
+    {
+        void initialize(T component) throws Exception;
+    }
+
     protected final DecoratedObjectFactory _objFactory;
     protected Class<? extends SecurityHandler> _defaultSecurityHandlerClass = org.eclipse.jetty.security.ConstraintSecurityHandler.class;
     protected SessionHandler _sessionHandler;
@@ -129,6 +152,12 @@ public class ServletContextHandler extends ContextHandler
     protected JspConfigDescriptor _jspConfig;
 
     private boolean _startListeners;
+    private ServletInstanceDecorator _servletInstanceDecorator;
+    private ServletInstanceDecorator _instanceDecorator;
+    private boolean _parallelInitialization;
+    private int _initializationParallelism = ProcessorUtils.availableProcessors();
+    private boolean _startupProfiling;
+    private StartupProfile _startupProfile;
 
     public ServletContextHandler()
     {
@@ -303,6 +332,13 @@ public class ServletContextHandler extends ContextHandler
     {
         _objFactory.addDecorator(new DeprecationWarning());
         getServletContext().setAttribute(DecoratedObjectFactory.ATTR, _objFactory);
+        _instanceDecorator = _servletInstanceDecorator;
+        if (_instanceDecorator == null && getServer() != null)
+        {
+            ServletInstanceDecorator.Factory factory = getServer().getBean(ServletInstanceDecorator.Factory.class);
+            if (factory != null)
+                _instanceDecorator = factory.newServletInstanceDecorator(this);
+        }
         super.doStart();
     }
 
@@ -310,6 +346,7 @@ public class ServletContextHandler extends ContextHandler
     protected void doStop() throws Exception
     {
         super.doStop();
+        _instanceDecorator = null;
         _objFactory.clear();
         getServletContext().removeAttribute(DecoratedObjectFactory.ATTR);
     }
@@ -362,11 +399,24 @@ public class ServletContextHandler extends ContextHandler
     @Override
     protected void startContext() throws Exception
     {
+        String parallelInitialization = getInitParameter(PARALLEL_INITIALIZATION);
+        if (parallelInitialization != null)
+            setParallelInitialization(Boolean.parseBoolean(parallelInitialization));
+        String startupProfiling = getInitParameter(STARTUP_PROFILING);
+        if (startupProfiling != null)
+            setStartupProfiling(Boolean.parseBoolean(startupProfiling));
+        _startupProfile = _startupProfiling ? new StartupProfile() : null;
+
         for (ServletContainerInitializerCaller  sci : getBeans(ServletContainerInitializerCaller.class))
         {
             if (sci.isStopped())
             {
+                long begin = NanoTime.now();
+                if (sci instanceof ServletContainerInitializerStarter)
+                    startInitializers((ServletContainerInitializerStarter)sci);
                 sci.start();
+                if (_startupProfile != null && !(sci instanceof ServletContainerInitializerStarter))
+                    _startupProfile.record(StartupProfile.Type.INITIALIZER, String.valueOf(sci), begin);
                 if (isAuto(sci))
                     manage(sci);
             }
@@ -390,6 +440,184 @@ public class ServletContextHandler extends ContextHandler
         // OK to Initialize servlet handler now that all relevant object trees have been started
         if (_servletHandler != null)
             _servletHandler.initialize();
+
+        if (_startupProfile != null)
+        {
+            _startupProfile.complete();
+            LOG.info("Startup profile of {}: {}", this, _startupProfile.getReport());
+        }
+    }
+
+    private void startInitializers(ServletContainerInitializerStarter starter) throws Exception
+    {
+        List<ServletContainerInitializerHolder> holders = starter.getBeans(ServletContainerInitializerHolder.class);
+        Initialization<ServletContainerInitializerHolder> initialization = holder ->
+        {
+            long begin = NanoTime.now();
+            try
+            {
+                holder.start();
+            }
+            finally
+            {
+                if (_startupProfile != null)
+                    _startupProfile.record(StartupProfile.Type.INITIALIZER, holder.getClassName(), begin);
+            }
+        };
+        // Initializers with an explicit order are started sequentially.
+        if (starter.isOrdered())
+        {
+            for (ServletContainerInitializerHolder holder : holders)
+            {
+                initialization.initialize(holder);
+            }
+        }
+        else
+        {
+            initialize(holders, initialization);
+        }
+    }
+
+    /**
+     * <p>Initializes the given components, concurrently if {@link #isParallelInitialization()
+     * parallel initialization} is enabled, using the server thread pool
+     * and the calling thread, within the scope of this context.</p>
+     * <p>This method returns when all the components are initialized.</p>
+     *
+     * @param components the components to initialize
+     * @param initialization the initialization of a component
+     * @param <T> the type of the components
+     * @throws Exception if the initialization of any component fails
+     */
+    <T> void initialize(List<T> components, Initialization<T> initialization) throws Exception
+    {
+        int parallelism = isParallelInitialization() ? Math.min(getInitializationParallelism(), components.size()) : 1;
+        Executor executor = getServer() == null ? null : getServer().getThreadPool();
+        if (parallelism <= 1 || executor == null)
+        {
+            for (T component : components)
+            {
+                initialization.initialize(component);
+            }
+            return;
+        }
+
+        MultiException failures = new MultiException();
+        AtomicInteger next = new AtomicInteger();
+        CountDownLatch complete = new CountDownLatch(components.size());
+        Runnable worker = () -> handle(() ->
+        {
+            int index;
+            while ((index = next.getAndIncrement()) < components.size())
+            {
+                try
+                {
+                    initialization.initialize(components.get(index));
+                }
+                catch (Throwable x)
+                {
+                    synchronized (failures)
+                    {
+                        failures.add(x);
+                    }
+                }
+                finally
+                {
+                    complete.countDown();
+                }
+            }
+        });
+
+        for (int i = 1; i < parallelism; ++i)
+        {
+            try
+            {
+                executor.execute(worker);
+            }
+            catch (RejectedExecutionException x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Unable to initialize concurrently {}", this, x);
+                break;
+            }
+        }
+        // The calling thread also initializes components, so that
+        // the initialization completes even if no thread is available.
+        worker.run();
+        complete.await();
+        failures.ifExceptionThrow();
+    }
+
+    /**
+     * @return whether initializers, filters and servlets are initialized concurrently
+     */
+    @ManagedAttribute("Whether initializers, filters and servlets are initialized concurrently")
+    public boolean isParallelInitialization()
+    {
+        return _parallelInitialization;
+    }
+
+    /**
+     * <p>Sets whether the {@link ServletContainerInitializer}s, the filters and the servlets
+     * of this context are initialized concurrently, to reduce the startup time.</p>
+     * <p>Explicit ordering constraints are respected: initializers that are explicitly
+     * ordered are started sequentially, filters are initialized before servlets, and
+     * servlets with a lower {@code load-on-startup} value are initialized before servlets
+     * with a higher value, while only servlets with the same value are initialized concurrently.
+     * {@link ServletContextListener}s are always called sequentially.</p>
+     * <p>Note that filters registered concurrently by initializers may be mapped in
+     * a different order at every start.</p>
+     *
+     * @param parallelInitialization whether to initialize concurrently
+     * @see #PARALLEL_INITIALIZATION
+     */
+    public void setParallelInitialization(boolean parallelInitialization)
+    {
+        _parallelInitialization = parallelInitialization;
+    }
+
+    @ManagedAttribute("The max number of components initialized concurrently")
+    public int getInitializationParallelism()
+    {
+        return _initializationParallelism;
+    }
+
+    /**
+     * @param initializationParallelism the max number of components initialized concurrently,
+     * by default the number of available processors
+     */
+    public void setInitializationParallelism(int initializationParallelism)
+    {
+        _initializationParallelism = initializationParallelism;
+    }
+
+    @ManagedAttribute("Whether the startup time of initializers, listeners, filters and servlets is profiled")
+    public boolean isStartupProfiling()
+    {
+        return _startupProfiling;
+    }
+
+    /**
+     * <p>Sets whether the time spent initializing each {@link ServletContainerInitializer},
+     * {@link ServletContextListener}, filter and servlet is recorded in a {@link StartupProfile},
+     * which is logged when this context is started.</p>
+     *
+     * @param startupProfiling whether to profile the startup of this context
+     * @see #STARTUP_PROFILING
+     * @see #getStartupProfile()
+     */
+    public void setStartupProfiling(boolean startupProfiling)
+    {
+        _startupProfiling = startupProfiling;
+    }
+
+    /**
+     * @return the profile of the last start of this context, or {@code null} if startup profiling is disabled
+     */
+    @ManagedAttribute(value = "The startup profile", readonly = true)
+    public StartupProfile getStartupProfile()
+    {
+        return _startupProfile;
     }
 
     @Override
@@ -621,7 +849,17 @@ public class ServletContextHandler extends ContextHandler
             if (isProgrammaticListener(l))
                 this.getServletContext().setEnabled(false);
 
-            super.callContextInitialized(l, e);
+            long begin = NanoTime.now();
+            try
+            {
+                super.callContextInitialized(l, e);
+            }
+            finally
+            {
+                StartupProfile profile = _startupProfile;
+                if (profile != null)
+                    profile.record(StartupProfile.Type.LISTENER, l.getClass().getName(), begin);
+            }
         }
         finally
         {
@@ -757,19 +995,50 @@ public class ServletContextHandler extends ContextHandler
         return _objFactory;
     }
 
+    /**
+     * @return the SPI that creates the servlets, filters and listeners of this context, or null if none
+     */
+    public ServletInstanceDecorator getServletInstanceDecorator()
+    {
+        return _servletInstanceDecorator;
+    }
+
+    /**
+     * <p>Sets the SPI that creates the servlets, filters and listeners of this context,
+     * for example from an external dependency injection container.</p>
+     * <p>If not set, a {@link ServletInstanceDecorator.Factory} bean of the {@code Server},
+     * if any, is used to create one when this context starts.</p>
+     *
+     * @param servletInstanceDecorator the SPI that creates the servlets, filters and listeners of this context
+     */
+    public void setServletInstanceDecorator(ServletInstanceDecorator servletInstanceDecorator)
+    {
+        if (isStarted())
+            throw new IllegalStateException(getState());
+        _servletInstanceDecorator = servletInstanceDecorator;
+    }
+
     void destroyServlet(Servlet servlet)
     {
-        _objFactory.destroy(servlet);
+        destroyInstance(servlet);
     }
 
     void destroyFilter(Filter filter)
     {
-        _objFactory.destroy(filter);
+        destroyInstance(filter);
     }
 
     void destroyListener(EventListener listener)
     {
-        _objFactory.destroy(listener);
+        destroyInstance(listener);
+    }
+
+    private void destroyInstance(Object instance)
+    {
+        ServletInstanceDecorator decorator = _instanceDecorator;
+        if (decorator != null)
+            decorator.preDestroy(instance);
+        _objFactory.destroy(instance);
     }
 
     public static class JspPropertyGroup implements JspPropertyGroupDescriptor
@@ -1289,7 +1558,30 @@ public class ServletContextHandler extends ContextHandler
         @Override
         public <T> T createInstance(Class<T> clazz) throws ServletException
         {
-            return _objFactory.decorate(super.createInstance(clazz));
+            ServletInstanceDecorator decorator = _instanceDecorator;
+            T instance = null;
+            if (decorator != null)
+            {
+                try
+                {
+                    Object info = DecoratedObjectFactory.getAssociatedInfo();
+                    instance = decorator.newInstance(clazz, info instanceof BaseHolder ? (BaseHolder<?>)info : null);
+                }
+                catch (ServletException x)
+                {
+                    throw x;
+                }
+                catch (Exception x)
+                {
+                    throw new ServletException(x);
+                }
+            }
+            if (instance == null)
+                instance = super.createInstance(clazz);
+            instance = _objFactory.decorate(instance);
+            if (decorator != null)
+                decorator.postConstruct(instance);
+            return instance;
         }
 
         public <T> T createInstance(BaseHolder<T> holder) throws ServletException
@@ -1309,12 +1601,12 @@ public class ServletContextHandler extends ContextHandler
 
         public <T extends Filter> void destroyFilter(T f)
         {
-            _objFactory.destroy(f);
+            destroyInstance(f);
         }
 
         public <T extends Servlet> void destroyServlet(T s)
         {
-            _objFactory.destroy(s);
+            destroyInstance(s);
         }
 
         @Override
@@ -1639,6 +1931,25 @@ public class ServletContextHandler extends ContextHandler
      */
     public static class ServletContainerInitializerStarter extends ContainerLifeCycle implements ServletContainerInitializerCaller
     {
+        private boolean _ordered;
+
+        /**
+         * @return whether the SCIs have an explicit order, so that they are never started concurrently
+         */
+        public boolean isOrdered()
+        {
+            return _ordered;
+        }
+
+        /**
+         * @param ordered whether the SCIs have an explicit order, so that they are never started concurrently
+         * @see ServletContextHandler#setParallelInitialization(boolean)
+         */
+        public void setOrdered(boolean ordered)
+        {
+            _ordered = ordered;
+        }
+
         public void addServletContainerInitializerHolders(ServletContainerInitializerHolder... holders)
         {
             for (ServletContainerInitializerHolder holder:holders)
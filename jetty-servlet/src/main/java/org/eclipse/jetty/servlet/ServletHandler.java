@@ -29,7 +29,6 @@ import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.ConcurrentMap;
 import java.util.function.Consumer;
 import java.util.stream.Collectors;
-import java.util.stream.Stream;
 import javax.servlet.DispatcherType;
 import javax.servlet.Filter;
 import javax.servlet.FilterChain;
@@ -62,6 +61,7 @@ import org.eclipse.jetty.server.handler.ScopedHandler;
 import org.eclipse.jetty.util.ArrayUtil;
 import org.eclipse.jetty.util.MultiException;
 import org.eclipse.jetty.util.MultiMap;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.component.DumpableCollection;
@@ -714,9 +714,11 @@ public class ServletHandler extends ScopedHandler
         throws Exception
     {
         MultiException mx = new MultiException();
+        StartupProfile profile = _contextHandler == null ? null : _contextHandler.getStartupProfile();
 
         Consumer<BaseHolder<?>> c = h ->
         {
+            long begin = NanoTime.now();
             try
             {
                 if (!h.isStarted())
@@ -728,7 +730,15 @@ public class ServletHandler extends ScopedHandler
             catch (Throwable e)
             {
                 LOG.debug("Unable to start {}", h, e);
-                mx.add(e);
+                synchronized (mx)
+                {
+                    mx.add(e);
+                }
+            }
+            finally
+            {
+                if (profile != null && h instanceof Holder)
+                    profile.record(h instanceof FilterHolder ? StartupProfile.Type.FILTER : StartupProfile.Type.SERVLET, ((Holder<?>)h).getName(), begin);
             }
         };
         
@@ -742,15 +752,31 @@ public class ServletHandler extends ScopedHandler
         //Only set initialized true AFTER the listeners have been called
         _initialized = true;
             
-        //Start the filters then the servlets
-        Stream.concat(
-            _filters.stream(),
-            _servlets.stream().sorted())
-            .forEach(c);
+        //Start the filters then the servlets, with the servlets
+        //of the same init order possibly started concurrently
+        initialize(new ArrayList<>(_filters), c);
+        List<ServletHolder> servlets = _servlets.stream().sorted().collect(Collectors.toList());
+        int from = 0;
+        for (int i = 1; i <= servlets.size(); ++i)
+        {
+            if (i == servlets.size() || servlets.get(i).getInitOrder() != servlets.get(from).getInitOrder())
+            {
+                initialize(servlets.subList(from, i), c);
+                from = i;
+            }
+        }
 
         mx.ifExceptionThrow();
     }
     
+    private void initialize(List<? extends BaseHolder<?>> holders, Consumer<BaseHolder<?>> c) throws Exception
+    {
+        if (_contextHandler == null)
+            holders.forEach(c);
+        else
+            _contextHandler.initialize(holders, c::accept);
+    }
+
     /**
      * @return true if initialized has been called, false otherwise
      */
@@ -0,0 +1,77 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.servlet;
+
+/**
+ * <p>A SPI that allows an external dependency injection container, such as
+ * Guice, Spring or Dagger, to create the servlets, filters and listeners
+ * of a {@link ServletContextHandler}, for example via constructor injection,
+ * without a full CDI integration.</p>
+ * <p>An instance of this interface may be configured on a specific context via
+ * {@link ServletContextHandler#setServletInstanceDecorator(ServletInstanceDecorator)},
+ * or a {@link Factory} may be added as a bean to the {@code Server}, so that every
+ * context gets its own instance when it starts, for example backed by a child
+ * injector that isolates the bindings of the context.</p>
+ * <p>Instances created by this SPI are still decorated by the
+ * {@link ServletContextHandler#getObjectFactory() object factory} of the context,
+ * so that the standard lifecycle annotations and resource injections are processed.
+ * The lifecycle callbacks of this SPI are invoked after the context decoration
+ * and before the context destruction, respectively.</p>
+ */
+public interface ServletInstanceDecorator
+{
+    /**
+     * <p>Creates an instance of the given class.</p>
+     *
+     * @param clazz the class of the servlet, filter or listener to create
+     * @param holder the holder of the instance, or null if the instance is created via
+     * the {@link javax.servlet.ServletContext} {@code create*()} methods
+     * @param <T> the type of the instance
+     * @return the instance, or null to let the context create the instance with the default constructor
+     * @throws Exception if the instance cannot be created
+     */
+    <T> T newInstance(Class<T> clazz, BaseHolder<?> holder) throws Exception;
+
+    /**
+     * <p>Callback method invoked after the instance has been created and decorated by the context.</p>
+     *
+     * @param instance the servlet, filter or listener instance
+     */
+    default void postConstruct(Object instance)
+    {
+    }
+
+    /**
+     * <p>Callback method invoked before the instance is destroyed by the context.</p>
+     *
+     * @param instance the servlet, filter or listener instance
+     */
+    default void preDestroy(Object instance)
+    {
+    }
+
+    /**
+     * <p>A factory of {@link ServletInstanceDecorator}s, one for each context.</p>
+     * <p>Factories are looked up as beans of the {@code Server} when a context
+     * that has no explicit {@link ServletInstanceDecorator} starts.</p>
+     */
+    interface Factory
+    {
+        /**
+         * @param context the context that is starting
+         * @return the decorator for the given context, or null if the context instances are not created externally
+         */
+        ServletInstanceDecorator newServletInstanceDecorator(ServletContextHandler context);
+    }
+}
@@ -0,0 +1,190 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.servlet;
+
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.Comparator;
+import java.util.List;
+import java.util.Queue;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.component.Dumpable;
+
+/**
+ * <p>The time spent initializing the components of a {@link ServletContextHandler}:
+ * its {@link javax.servlet.ServletContainerInitializer}s, its
+ * {@link javax.servlet.ServletContextListener}s, its filters and its servlets.</p>
+ * <p>A profile is recorded when {@link ServletContextHandler#setStartupProfiling(boolean)}
+ * is enabled, and it is available from {@link ServletContextHandler#getStartupProfile()}
+ * once the context is started.</p>
+ */
+@ManagedObject("The startup profile of a servlet context")
+public class StartupProfile implements Dumpable
+{
+    public enum Type
+    {
+        INITIALIZER, LISTENER, FILTER, SERVLET
+    }
+
+    private final Queue<Entry> _entries = new ConcurrentLinkedQueue<>();
+    private final long _beginNanoTime = NanoTime.now();
+    private volatile long _endNanoTime;
+
+    /**
+     * @param type the type of the component
+     * @param name the name of the component
+     * @param beginNanoTime the {@link NanoTime} at which the initialization of the component began
+     */
+    public void record(Type type, String name, long beginNanoTime)
+    {
+        long endNanoTime = NanoTime.now();
+        _entries.add(new Entry(type, name, Thread.currentThread().getName(), NanoTime.elapsed(_beginNanoTime, beginNanoTime), NanoTime.elapsed(beginNanoTime, endNanoTime)));
+    }
+
+    void complete()
+    {
+        _endNanoTime = NanoTime.now();
+    }
+
+    /**
+     * @return the entries of this profile, sorted by descending duration
+     */
+    public List<Entry> getEntries()
+    {
+        List<Entry> entries = new ArrayList<>(_entries);
+        entries.sort(Comparator.comparingLong((Entry entry) -> entry._durationNanos).reversed());
+        return entries;
+    }
+
+    /**
+     * @return the time in milliseconds to start the context, or {@code -1} if the context is still starting
+     */
+    @ManagedAttribute(value = "The time in milliseconds to start the context", readonly = true)
+    public long getTotalTime()
+    {
+        long endNanoTime = _endNanoTime;
+        return endNanoTime == 0 ? -1 : NanoTime.millisElapsed(_beginNanoTime, endNanoTime);
+    }
+
+    /**
+     * @param type the type of the components
+     * @return the cumulated time in milliseconds spent initializing the components of the given type
+     */
+    public long getTime(Type type)
+    {
+        long nanos = _entries.stream()
+            .filter(entry -> entry.getType() == type)
+            .mapToLong(entry -> entry._durationNanos)
+            .sum();
+        return TimeUnit.NANOSECONDS.toMillis(nanos);
+    }
+
+    /**
+     * @return a report listing the time spent initializing each component, slowest first
+     */
+    @ManagedAttribute(value = "The report of the startup profile", readonly = true)
+    public String getReport()
+    {
+        StringBuilder builder = new StringBuilder();
+        builder.append(String.format("total=%dms initializers=%dms listeners=%dms filters=%dms servlets=%dms",
+            getTotalTime(),
+            getTime(Type.INITIALIZER),
+            getTime(Type.LISTENER),
+            getTime(Type.FILTER),
+            getTime(Type.SERVLET)));
+        for (Entry entry : getEntries())
+        {
+            builder.append(System.lineSeparator()).append("  ").append(entry);
+        }
+        return builder.toString();
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        Dumpable.dumpObjects(out, indent, this, getEntries().toArray());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[total=%dms,entries=%d]", getClass().getSimpleName(), hashCode(), getTotalTime(), _entries.size());
+    }
+
+    /**
+     * <p>The initialization of a component.</p>
+     */
+    public static class Entry
+    {
+        private final Type _type;
+        private final String _name;
+        private final String _thread;
+        private final long _offsetNanos;
+        private final long _durationNanos;
+
+        private Entry(Type type, String name, String thread, long offsetNanos, long durationNanos)
+        {
+            _type = type;
+            _name = name;
+            _thread = thread;
+            _offsetNanos = offsetNanos;
+            _durationNanos = durationNanos;
+        }
+
+        public Type getType()
+        {
+            return _type;
+        }
+
+        public String getName()
+        {
+            return _name;
+        }
+
+        /**
+         * @return the name of the thread that initialized the component
+         */
+        public String getThread()
+        {
+            return _thread;
+        }
+
+        /**
+         * @return the time in milliseconds since the start of the context at which the initialization began
+         */
+        public long getOffset()
+        {
+            return TimeUnit.NANOSECONDS.toMillis(_offsetNanos);
+        }
+
+        /**
+         * @return the time in milliseconds spent initializing the component
+         */
+        public long getDuration()
+        {
+            return TimeUnit.NANOSECONDS.toMillis(_durationNanos);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s %s %dms (at +%dms on %s)", _type, _name, getDuration(), getOffset(), _thread);
+        }
+    }
+}
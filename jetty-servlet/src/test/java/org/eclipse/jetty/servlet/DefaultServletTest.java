@@ -2141,6 +2141,35 @@ public class DefaultServletTest
         assertThat(response.toString(), response.getStatus(), is(HttpStatus.PRECONDITION_FAILED_412));
     }
 
+    @Test
+    public void testAttachmentFileExtensions() throws Exception
+    {
+        FS.ensureEmpty(docRoot);
+
+        ServletHolder defholder = context.addServlet(DefaultServlet.class, "/");
+        defholder.setInitParameter("attachmentFileExtensions", "zip, .pdf");
+        context.addAliasCheck(new AllowedResourceAliasChecker(context));
+
+        createFile(docRoot.resolve("report.pdf"), "pdf");
+        createFile(docRoot.resolve("r\u00E9sum\u00E9.zip"), "zip");
+        createFile(docRoot.resolve("index.txt"), "txt");
+
+        String rawResponse = connector.getResponse("GET /context/report.pdf HTTP/1.1\r\nHost:test\r\nConnection:close\r\n\r\n");
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_DISPOSITION), is("attachment; filename=\"report.pdf\""));
+
+        rawResponse = connector.getResponse("GET /context/r%C3%A9sum%C3%A9.zip HTTP/1.1\r\nHost:test\r\nConnection:close\r\n\r\n");
+        response = HttpTester.parseResponse(rawResponse);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_DISPOSITION), is("attachment; filename=\"r_sum_.zip\"; filename*=UTF-8''r%C3%A9sum%C3%A9.zip"));
+
+        rawResponse = connector.getResponse("GET /context/index.txt HTTP/1.1\r\nHost:test\r\nConnection:close\r\n\r\n");
+        response = HttpTester.parseResponse(rawResponse);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.CONTENT_DISPOSITION), nullValue());
+    }
+
     @Test
     public void testGetUtf8NfcFile() throws Exception
     {
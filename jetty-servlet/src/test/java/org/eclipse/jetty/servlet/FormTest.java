@@ -13,6 +13,7 @@
 
 package org.eclipse.jetty.servlet;
 
+import java.io.IOException;
 import java.nio.ByteBuffer;
 import java.nio.charset.StandardCharsets;
 import java.util.Arrays;
@@ -34,10 +35,12 @@ import org.eclipse.jetty.server.Server;
 import org.eclipse.jetty.server.ServerConnector;
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.util.Fields;
+import org.eclipse.jetty.util.ParameterParser;
 import org.junit.jupiter.api.AfterEach;
 import org.junit.jupiter.params.ParameterizedTest;
 import org.junit.jupiter.params.provider.Arguments;
 import org.junit.jupiter.params.provider.MethodSource;
+import org.junit.jupiter.params.provider.ValueSource;
 
 import static org.junit.jupiter.api.Assertions.assertEquals;
 
@@ -195,4 +198,43 @@ public class FormTest
             : HttpStatus.BAD_REQUEST_400;
         assertEquals(expected, response.getStatus());
     }
+
+    @ParameterizedTest
+    @ValueSource(booleans = {false, true})
+    public void testMaxFormValueLengthExceeded(boolean tolerate) throws Exception
+    {
+        start(handler ->
+        {
+            handler.setMaxFormValueLength(4);
+            if (tolerate)
+                handler.setParameterViolationListener((violation, message) -> violation == ParameterParser.Violation.VALUE_TOO_LONG);
+            return new HttpServlet()
+            {
+                @Override
+                protected void service(HttpServletRequest request, HttpServletResponse response) throws IOException
+                {
+                    response.getWriter().print(request.getParameterMap().keySet());
+                }
+            };
+        });
+
+        Fields formParams = new Fields();
+        formParams.add("short", "1234");
+        formParams.add("long", "12345");
+        ContentResponse response = client.newRequest("localhost", connector.getLocalPort())
+            .method(HttpMethod.POST)
+            .path(contextPath + servletPath)
+            .body(new FormRequestContent(formParams))
+            .send();
+
+        if (tolerate)
+        {
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("[short]", response.getContentAsString());
+        }
+        else
+        {
+            assertEquals(HttpStatus.BAD_REQUEST_400, response.getStatus());
+        }
+    }
 }
@@ -24,6 +24,7 @@ import java.util.HashSet;
 import java.util.List;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.CyclicBarrier;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicInteger;
@@ -92,6 +93,8 @@ import org.slf4j.LoggerFactory;
 
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.hasItems;
 import static org.hamcrest.Matchers.instanceOf;
 import static org.hamcrest.Matchers.notNullValue;
 import static org.hamcrest.Matchers.nullValue;
@@ -1070,6 +1073,77 @@ public class ServletContextHandlerTest
         assertEquals(0, __testServlets.get());
     }
 
+    @Test
+    public void testParallelInitialization() throws Exception
+    {
+        CyclicBarrier barrier = new CyclicBarrier(2);
+        AtomicInteger initialized = new AtomicInteger();
+        AtomicInteger initializedBeforeLast = new AtomicInteger(-1);
+        class BarrierServlet extends HttpServlet
+        {
+            @Override
+            public void init() throws ServletException
+            {
+                try
+                {
+                    // Only completes if both servlets are initialized concurrently.
+                    barrier.await(5, TimeUnit.SECONDS);
+                    initialized.incrementAndGet();
+                }
+                catch (Exception x)
+                {
+                    throw new ServletException(x);
+                }
+            }
+        }
+
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        context.setParallelInitialization(true);
+        context.setInitializationParallelism(2);
+        context.setStartupProfiling(true);
+        // The SCI adds a MyContextListener.
+        context.addServletContainerInitializer(new MySCI());
+        ServletHolder holder1 = new ServletHolder("one", new BarrierServlet());
+        holder1.setInitOrder(1);
+        context.addServlet(holder1, "/one");
+        ServletHolder holder2 = new ServletHolder("two", new BarrierServlet());
+        holder2.setInitOrder(1);
+        context.addServlet(holder2, "/two");
+        ServletHolder holder3 = new ServletHolder("three", new HttpServlet()
+        {
+            @Override
+            public void init()
+            {
+                initializedBeforeLast.set(initialized.get());
+            }
+        });
+        holder3.setInitOrder(2);
+        context.addServlet(holder3, "/three");
+
+        context.setContextPath("/");
+        _server.setHandler(context);
+        _server.start();
+
+        // The servlet with a higher init order is initialized last.
+        assertEquals(2, initializedBeforeLast.get());
+
+        StartupProfile profile = context.getStartupProfile();
+        assertNotNull(profile);
+        assertThat(profile.getTotalTime(), greaterThanOrEqualTo(0L));
+        List<String> entries = new ArrayList<>();
+        for (StartupProfile.Entry entry : profile.getEntries())
+        {
+            entries.add(entry.getType() + " " + entry.getName());
+        }
+        assertThat(entries, hasItems(
+            "INITIALIZER " + MySCI.class.getName(),
+            "LISTENER " + MyContextListener.class.getName(),
+            "SERVLET one",
+            "SERVLET two",
+            "SERVLET three"));
+        assertThat(profile.getReport(), containsString("SERVLET one"));
+    }
+
     @Test
     public void testAddServletFromServlet()
     {
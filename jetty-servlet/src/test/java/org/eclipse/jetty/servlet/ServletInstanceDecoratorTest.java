@@ -0,0 +1,241 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.servlet;
+
+import java.io.IOException;
+import java.util.EnumSet;
+import java.util.List;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.function.Supplier;
+import javax.servlet.DispatcherType;
+import javax.servlet.Filter;
+import javax.servlet.FilterChain;
+import javax.servlet.ServletContextEvent;
+import javax.servlet.ServletContextListener;
+import javax.servlet.ServletException;
+import javax.servlet.ServletRequest;
+import javax.servlet.ServletResponse;
+import javax.servlet.http.HttpServlet;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.ContextHandlerCollection;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.hasItem;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThan;
+import static org.hamcrest.Matchers.sameInstance;
+
+public class ServletInstanceDecoratorTest
+{
+    private final List<String> events = new CopyOnWriteArrayList<>();
+    private Server server;
+    private LocalConnector connector;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private ServletContextHandler newContext(String contextPath)
+    {
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.NO_SESSIONS);
+        context.setContextPath(contextPath);
+        context.addServlet(GreetingServlet.class, "/greet");
+        context.addFilter(GreetingFilter.class, "/*", EnumSet.of(DispatcherType.REQUEST));
+        context.getServletHandler().addListener(new ListenerHolder(GreetingListener.class));
+        return context;
+    }
+
+    private String get(String path) throws Exception
+    {
+        String request = "GET " + path + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+        return HttpTester.parseResponse(connector.getResponse(request)).getContent();
+    }
+
+    @Test
+    public void testConstructorInjection() throws Exception
+    {
+        ServletContextHandler context = newContext("/");
+        List<BaseHolder<?>> holders = new CopyOnWriteArrayList<>();
+        context.setServletInstanceDecorator(new Injector(() -> "hello", holders));
+        server.setHandler(context);
+        server.start();
+
+        assertThat(get("/greet"), is("filter:hello servlet:hello"));
+        assertThat(events, hasItem("postConstruct:GreetingServlet"));
+        assertThat(events, hasItem("postConstruct:GreetingFilter"));
+        // The listener is created and decorated before it is notified.
+        assertThat(events.indexOf("postConstruct:GreetingListener"), greaterThanOrEqualTo(0));
+        assertThat(events.indexOf("postConstruct:GreetingListener"), lessThan(events.indexOf("contextInitialized:hello")));
+        assertThat(holders.size(), is(3));
+        assertThat(holders.stream().anyMatch(ListenerHolder.class::isInstance), is(true));
+
+        events.clear();
+        server.stop();
+        assertThat(events, hasItem("preDestroy:GreetingServlet"));
+        assertThat(events, hasItem("preDestroy:GreetingFilter"));
+        assertThat(events, hasItem("preDestroy:GreetingListener"));
+    }
+
+    @Test
+    public void testPerContextFactory() throws Exception
+    {
+        ServletContextHandler context1 = newContext("/one");
+        ServletContextHandler context2 = newContext("/two");
+        List<ServletContextHandler> contexts = new CopyOnWriteArrayList<>();
+        server.addBean((ServletInstanceDecorator.Factory)context ->
+        {
+            contexts.add(context);
+            String path = context.getContextPath();
+            return new Injector(() -> path.substring(1), new CopyOnWriteArrayList<>());
+        });
+        server.setHandler(new ContextHandlerCollection(context1, context2));
+        server.start();
+
+        assertThat(get("/one/greet"), is("filter:one servlet:one"));
+        assertThat(get("/two/greet"), is("filter:two servlet:two"));
+        assertThat(contexts.size(), is(2));
+        assertThat(contexts.get(0), sameInstance(context1));
+    }
+
+    @Test
+    public void testFallbackToDefaultConstructor() throws Exception
+    {
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.NO_SESSIONS);
+        context.addServlet(DefaultConstructorServlet.class, "/default");
+        context.setServletInstanceDecorator((clazz, holder) -> null);
+        server.setHandler(context);
+        server.start();
+
+        assertThat(get("/default"), is("default"));
+    }
+
+    private class Injector implements ServletInstanceDecorator
+    {
+        private final Supplier<String> greeting;
+        private final List<BaseHolder<?>> holders;
+
+        private Injector(Supplier<String> greeting, List<BaseHolder<?>> holders)
+        {
+            this.greeting = greeting;
+            this.holders = holders;
+        }
+
+        @Override
+        public <T> T newInstance(Class<T> clazz, BaseHolder<?> holder) throws Exception
+        {
+            try
+            {
+                T instance = clazz.getConstructor(Supplier.class, List.class).newInstance(greeting, events);
+                holders.add(holder);
+                return instance;
+            }
+            catch (NoSuchMethodException x)
+            {
+                // Not an injectable class, such as the default servlet.
+                return null;
+            }
+        }
+
+        @Override
+        public void postConstruct(Object instance)
+        {
+            events.add("postConstruct:" + instance.getClass().getSimpleName());
+        }
+
+        @Override
+        public void preDestroy(Object instance)
+        {
+            events.add("preDestroy:" + instance.getClass().getSimpleName());
+        }
+    }
+
+    public static class GreetingServlet extends HttpServlet
+    {
+        private final Supplier<String> greeting;
+
+        public GreetingServlet(Supplier<String> greeting, List<String> events)
+        {
+            this.greeting = greeting;
+        }
+
+        @Override
+        protected void doGet(HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            response.getWriter().print(request.getAttribute("filter") + " servlet:" + greeting.get());
+        }
+    }
+
+    public static class GreetingFilter implements Filter
+    {
+        private final Supplier<String> greeting;
+
+        public GreetingFilter(Supplier<String> greeting, List<String> events)
+        {
+            this.greeting = greeting;
+        }
+
+        @Override
+        public void doFilter(ServletRequest request, ServletResponse response, FilterChain chain) throws IOException, ServletException
+        {
+            request.setAttribute("filter", "filter:" + greeting.get());
+            chain.doFilter(request, response);
+        }
+    }
+
+    public static class GreetingListener implements ServletContextListener
+    {
+        private final Supplier<String> greeting;
+        private final List<String> events;
+
+        public GreetingListener(Supplier<String> greeting, List<String> events)
+        {
+            this.greeting = greeting;
+            this.events = events;
+        }
+
+        @Override
+        public void contextInitialized(ServletContextEvent sce)
+        {
+            events.add("contextInitialized:" + greeting.get());
+        }
+    }
+
+    public static class DefaultConstructorServlet extends HttpServlet
+    {
+        @Override
+        protected void doGet(HttpServletRequest request, HttpServletResponse response) throws IOException
+        {
+            response.getWriter().print("default");
+        }
+    }
+}
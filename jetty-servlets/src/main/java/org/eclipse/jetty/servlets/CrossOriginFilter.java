@@ -21,6 +21,7 @@ import java.util.Enumeration;
 import java.util.HashSet;
 import java.util.List;
 import java.util.Set;
+import java.util.function.Function;
 import java.util.regex.Pattern;
 import javax.servlet.Filter;
 import javax.servlet.FilterChain;
@@ -34,8 +35,10 @@ import javax.servlet.http.HttpServletResponse;
 import org.eclipse.jetty.http.HttpField;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.PreEncodedHttpField;
+import org.eclipse.jetty.http.pathmap.ServletPathSpec;
 import org.eclipse.jetty.server.Response;
 import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.URIUtil;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -104,7 +107,41 @@ import org.slf4j.LoggerFactory;
  * target resource for normal handling (as an OPTION request).  Otherwise the
  * filter will response to the preflight. Default is <b>true</b>.</dd>
  *
+ * <dt>allowPrivateNetwork</dt>
+ * <dd>a boolean indicating if the resource allows requests from public
+ * networks, as specified by
+ * <a href="https://wicg.github.io/private-network-access/">Private Network Access</a>.
+ * If true, preflight requests with the <b>Access-Control-Request-Private-Network</b>
+ * header receive the <b>Access-Control-Allow-Private-Network</b> header.
+ * Default value is <b>false</b></dd>
+ *
+ * <dt>preflightCacheControl</dt>
+ * <dd>the value of the <b>Cache-Control</b> header of the successful preflight responses
+ * generated by this filter when <b>chainPreflight</b> is false, for example
+ * <b>public, max-age=600</b> to allow shared caches to store them. Default
+ * value is the empty string, meaning no <b>Cache-Control</b> header.</dd>
+ *
+ * <dt>policies</dt>
+ * <dd>a comma separated list of names of additional policies, that apply
+ * to a subset of the requests filtered by this filter. Each policy is configured
+ * with the parameters above, prefixed with the policy name and a dot, for example
+ * <b>api.allowedOrigins</b>, defaulting to the non-prefixed parameters, and with
+ * the following parameters:
+ * <ul>
+ * <li><b>&lt;name&gt;.paths</b>: a comma separated list of servlet path specs,
+ * relative to the context path, such as /api/*; if absent the policy applies to
+ * all paths</li>
+ * <li><b>&lt;name&gt;.origins</b>: a comma separated list of origins, with the same
+ * syntax of <b>allowedOrigins</b>; if absent the policy applies to all origins</li>
+ * </ul>
+ * The first policy, in the listed order, whose paths and origins match the request
+ * applies to the request; if no policy matches, the non-prefixed parameters apply.</dd>
+ *
  * </dl>
+ * <p>
+ * The allowed origins may also be validated dynamically, for example
+ * against a database of registered clients, by overriding
+ * {@link #isOriginAllowed(HttpServletRequest, String, String)}.
  * A typical configuration could be:
  * <pre>
  * &lt;web-app ...&gt;
@@ -120,6 +157,29 @@ import org.slf4j.LoggerFactory;
  *     ...
  * &lt;/web-app&gt;
  * </pre>
+ * A configuration with a stricter policy for an API could be:
+ * <pre>
+ * &lt;filter&gt;
+ *     &lt;filter-name&gt;cross-origin&lt;/filter-name&gt;
+ *     &lt;filter-class&gt;org.eclipse.jetty.servlets.CrossOriginFilter&lt;/filter-class&gt;
+ *     &lt;init-param&gt;
+ *         &lt;param-name&gt;policies&lt;/param-name&gt;
+ *         &lt;param-value&gt;api&lt;/param-value&gt;
+ *     &lt;/init-param&gt;
+ *     &lt;init-param&gt;
+ *         &lt;param-name&gt;api.paths&lt;/param-name&gt;
+ *         &lt;param-value&gt;/api/*&lt;/param-value&gt;
+ *     &lt;/init-param&gt;
+ *     &lt;init-param&gt;
+ *         &lt;param-name&gt;api.allowedOrigins&lt;/param-name&gt;
+ *         &lt;param-value&gt;https://app.example.com&lt;/param-value&gt;
+ *     &lt;/init-param&gt;
+ *     &lt;init-param&gt;
+ *         &lt;param-name&gt;api.allowedMethods&lt;/param-name&gt;
+ *         &lt;param-value&gt;GET,POST,PUT,DELETE&lt;/param-value&gt;
+ *     &lt;/init-param&gt;
+ * &lt;/filter&gt;
+ * </pre>
  */
 public class CrossOriginFilter implements Filter
 {
@@ -129,6 +189,7 @@ public class CrossOriginFilter implements Filter
     private static final String ORIGIN_HEADER = "Origin";
     public static final String ACCESS_CONTROL_REQUEST_METHOD_HEADER = "Access-Control-Request-Method";
     public static final String ACCESS_CONTROL_REQUEST_HEADERS_HEADER = "Access-Control-Request-Headers";
+    public static final String ACCESS_CONTROL_REQUEST_PRIVATE_NETWORK_HEADER = "Access-Control-Request-Private-Network";
     // Response headers
     public static final String ACCESS_CONTROL_ALLOW_ORIGIN_HEADER = "Access-Control-Allow-Origin";
     public static final String ACCESS_CONTROL_ALLOW_METHODS_HEADER = "Access-Control-Allow-Methods";
@@ -136,6 +197,7 @@ public class CrossOriginFilter implements Filter
     public static final String ACCESS_CONTROL_MAX_AGE_HEADER = "Access-Control-Max-Age";
     public static final String ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER = "Access-Control-Allow-Credentials";
     public static final String ACCESS_CONTROL_EXPOSE_HEADERS_HEADER = "Access-Control-Expose-Headers";
+    public static final String ACCESS_CONTROL_ALLOW_PRIVATE_NETWORK_HEADER = "Access-Control-Allow-Private-Network";
     public static final String TIMING_ALLOW_ORIGIN_HEADER = "Timing-Allow-Origin";
     // Implementation constants
     public static final String ALLOWED_ORIGINS_PARAM = "allowedOrigins";
@@ -147,6 +209,11 @@ public class CrossOriginFilter implements Filter
     public static final String EXPOSED_HEADERS_PARAM = "exposedHeaders";
     public static final String OLD_CHAIN_PREFLIGHT_PARAM = "forwardPreflight";
     public static final String CHAIN_PREFLIGHT_PARAM = "chainPreflight";
+    public static final String ALLOW_PRIVATE_NETWORK_PARAM = "allowPrivateNetwork";
+    public static final String PREFLIGHT_CACHE_CONTROL_PARAM = "preflightCacheControl";
+    public static final String POLICIES_PARAM = "policies";
+    public static final String POLICY_PATHS_PARAM = "paths";
+    public static final String POLICY_ORIGINS_PARAM = "origins";
     private static final String ANY_ORIGIN = "*";
     private static final String DEFAULT_ALLOWED_ORIGINS = "*";
     private static final String DEFAULT_ALLOWED_TIMING_ORIGINS = "";
@@ -155,115 +222,32 @@ public class CrossOriginFilter implements Filter
     private static final List<String> DEFAULT_ALLOWED_HEADERS = Arrays.asList("X-Requested-With", "Content-Type", "Accept", "Origin");
     private static final HttpField VARY_ORIGIN = new PreEncodedHttpField(HttpHeader.VARY, HttpHeader.ORIGIN.asString());
 
-    private boolean anyOriginAllowed;
-    private boolean anyTimingOriginAllowed;
-    private boolean anyHeadersAllowed;
-    private Set<String> allowedOrigins = new HashSet<String>();
-    private List<Pattern> allowedOriginPatterns = new ArrayList<Pattern>();
-    private Set<String> allowedTimingOrigins = new HashSet<String>();
-    private List<Pattern> allowedTimingOriginPatterns = new ArrayList<Pattern>();
-    private List<String> allowedMethods = new ArrayList<String>();
-    private List<String> allowedHeaders = new ArrayList<String>();
-    private List<String> exposedHeaders = new ArrayList<String>();
-    private int preflightMaxAge;
-    private boolean allowCredentials;
-    private boolean chainPreflight;
+    private final List<Policy> policies = new ArrayList<>();
+    private Policy defaultPolicy;
 
     @Override
     public void init(FilterConfig config) throws ServletException
     {
-        String allowedOriginsConfig = config.getInitParameter(ALLOWED_ORIGINS_PARAM);
-        String allowedTimingOriginsConfig = config.getInitParameter(ALLOWED_TIMING_ORIGINS_PARAM);
-
-        anyOriginAllowed = generateAllowedOrigins(allowedOrigins, allowedOriginPatterns, allowedOriginsConfig, DEFAULT_ALLOWED_ORIGINS);
-        anyTimingOriginAllowed = generateAllowedOrigins(allowedTimingOrigins, allowedTimingOriginPatterns, allowedTimingOriginsConfig, DEFAULT_ALLOWED_TIMING_ORIGINS);
-
-        String allowedMethodsConfig = config.getInitParameter(ALLOWED_METHODS_PARAM);
-        if (allowedMethodsConfig == null)
-            allowedMethods.addAll(DEFAULT_ALLOWED_METHODS);
-        else
-            allowedMethods.addAll(Arrays.asList(StringUtil.csvSplit(allowedMethodsConfig)));
-
-        String allowedHeadersConfig = config.getInitParameter(ALLOWED_HEADERS_PARAM);
-        if (allowedHeadersConfig == null)
-            allowedHeaders.addAll(DEFAULT_ALLOWED_HEADERS);
-        else if ("*".equals(allowedHeadersConfig))
-            anyHeadersAllowed = true;
-        else
-            allowedHeaders.addAll(Arrays.asList(StringUtil.csvSplit(allowedHeadersConfig)));
-
-        String preflightMaxAgeConfig = config.getInitParameter(PREFLIGHT_MAX_AGE_PARAM);
-        if (preflightMaxAgeConfig == null)
-            preflightMaxAgeConfig = "1800"; // Default is 30 minutes
-        try
-        {
-            preflightMaxAge = Integer.parseInt(preflightMaxAgeConfig);
-        }
-        catch (NumberFormatException x)
-        {
-            LOG.info("Cross-origin filter, could not parse '{}' parameter as integer: {}", PREFLIGHT_MAX_AGE_PARAM, preflightMaxAgeConfig);
-        }
-
-        String allowedCredentialsConfig = config.getInitParameter(ALLOW_CREDENTIALS_PARAM);
-        if (allowedCredentialsConfig == null)
-            allowedCredentialsConfig = "true";
-        allowCredentials = Boolean.parseBoolean(allowedCredentialsConfig);
-
-        String exposedHeadersConfig = config.getInitParameter(EXPOSED_HEADERS_PARAM);
-        if (exposedHeadersConfig == null)
-            exposedHeadersConfig = "";
-        exposedHeaders.addAll(Arrays.asList(StringUtil.csvSplit(exposedHeadersConfig)));
-
-        String chainPreflightConfig = config.getInitParameter(OLD_CHAIN_PREFLIGHT_PARAM);
-        if (chainPreflightConfig != null)
-            LOG.warn("DEPRECATED CONFIGURATION: Use {} instead of {}", CHAIN_PREFLIGHT_PARAM, OLD_CHAIN_PREFLIGHT_PARAM);
-        else
-            chainPreflightConfig = config.getInitParameter(CHAIN_PREFLIGHT_PARAM);
-        if (chainPreflightConfig == null)
-            chainPreflightConfig = "true";
-        chainPreflight = Boolean.parseBoolean(chainPreflightConfig);
-
-        if (LOG.isDebugEnabled())
-        {
-            LOG.debug("Cross-origin filter configuration: " +
-                ALLOWED_ORIGINS_PARAM + " = " + allowedOriginsConfig + ", " +
-                ALLOWED_TIMING_ORIGINS_PARAM + " = " + allowedTimingOriginsConfig + ", " +
-                ALLOWED_METHODS_PARAM + " = " + allowedMethodsConfig + ", " +
-                ALLOWED_HEADERS_PARAM + " = " + allowedHeadersConfig + ", " +
-                PREFLIGHT_MAX_AGE_PARAM + " = " + preflightMaxAgeConfig + ", " +
-                ALLOW_CREDENTIALS_PARAM + " = " + allowedCredentialsConfig + "," +
-                EXPOSED_HEADERS_PARAM + " = " + exposedHeadersConfig + "," +
-                CHAIN_PREFLIGHT_PARAM + " = " + chainPreflightConfig
-            );
-        }
-    }
+        defaultPolicy = new Policy(null, config::getInitParameter);
 
-    private boolean generateAllowedOrigins(Set<String> allowedOriginStore, List<Pattern> allowedOriginPatternStore, String allowedOriginsConfig, String defaultOrigin)
-    {
-        if (allowedOriginsConfig == null)
-            allowedOriginsConfig = defaultOrigin;
-        String[] allowedOrigins = StringUtil.csvSplit(allowedOriginsConfig);
-        for (String allowedOrigin : allowedOrigins)
+        String policiesConfig = config.getInitParameter(POLICIES_PARAM);
+        if (policiesConfig != null)
         {
-            if (allowedOrigin.length() > 0)
+            for (String name : StringUtil.csvSplit(policiesConfig))
             {
-                if (ANY_ORIGIN.equals(allowedOrigin))
+                if (name.isEmpty())
+                    continue;
+                // Policy parameters default to the non-prefixed parameters.
+                Function<String, String> policyConfig = param ->
                 {
-                    allowedOriginStore.clear();
-                    allowedOriginPatternStore.clear();
-                    return true;
-                }
-                else if (allowedOrigin.contains("*"))
-                {
-                    allowedOriginPatternStore.add(Pattern.compile(parseAllowedWildcardOriginToRegex(allowedOrigin)));
-                }
-                else
-                {
-                    allowedOriginStore.add(allowedOrigin);
-                }
+                    String value = config.getInitParameter(name + "." + param);
+                    return value != null ? value : config.getInitParameter(param);
+                };
+                Policy policy = new Policy(name, policyConfig);
+                policy.configureScope(config.getInitParameter(name + "." + POLICY_PATHS_PARAM), config.getInitParameter(name + "." + POLICY_ORIGINS_PARAM));
+                policies.add(policy);
             }
         }
-        return false;
     }
 
     @Override
@@ -282,46 +266,70 @@ public class CrossOriginFilter implements Filter
         // Is it a cross origin request ?
         if (origin != null && isEnabled(request))
         {
-            if (anyOriginAllowed || originMatches(allowedOrigins, allowedOriginPatterns, origin))
+            Policy policy = findPolicy(request, origin);
+            if (isOriginAllowed(request, policy.name, origin))
             {
                 if (isSimpleRequest(request))
                 {
                     LOG.debug("Cross-origin request to {} is a simple cross-origin request", request.getRequestURI());
-                    handleSimpleResponse(request, response, origin);
+                    policy.handleSimpleResponse(request, response, origin);
                 }
                 else if (isPreflightRequest(request))
                 {
                     LOG.debug("Cross-origin request to {} is a preflight cross-origin request", request.getRequestURI());
-                    handlePreflightResponse(request, response, origin);
-                    if (chainPreflight)
+                    boolean allowed = policy.handlePreflightResponse(request, response, origin);
+                    if (policy.chainPreflight)
+                    {
                         LOG.debug("Preflight cross-origin request to {} forwarded to application", request.getRequestURI());
+                    }
                     else
+                    {
+                        if (allowed && !policy.preflightCacheControl.isEmpty())
+                            response.setHeader(HttpHeader.CACHE_CONTROL.asString(), policy.preflightCacheControl);
                         return;
+                    }
                 }
                 else
                 {
                     LOG.debug("Cross-origin request to {} is a non-simple cross-origin request", request.getRequestURI());
-                    handleSimpleResponse(request, response, origin);
+                    policy.handleSimpleResponse(request, response, origin);
                 }
 
-                if (anyTimingOriginAllowed || originMatches(allowedTimingOrigins, allowedTimingOriginPatterns, origin))
+                if (policy.anyTimingOriginAllowed || originMatches(policy.allowedTimingOrigins, policy.allowedTimingOriginPatterns, origin))
                 {
                     response.setHeader(TIMING_ALLOW_ORIGIN_HEADER, origin);
                 }
                 else if (LOG.isDebugEnabled())
                 {
-                    LOG.debug("Cross-origin request to {} with origin {} does not match allowed timing origins {}", request.getRequestURI(), origin, allowedTimingOrigins);
+                    LOG.debug("Cross-origin request to {} with origin {} does not match allowed timing origins {}", request.getRequestURI(), origin, policy.allowedTimingOrigins);
                 }
             }
             else if (LOG.isDebugEnabled())
             {
-                LOG.debug("Cross-origin request to {} with origin {} does not match allowed origins {}", request.getRequestURI(), origin, allowedOrigins);
+                LOG.debug("Cross-origin request to {} with origin {} does not match allowed origins {}", request.getRequestURI(), origin, policy.allowedOrigins);
             }
         }
 
         chain.doFilter(request, response);
     }
 
+    private Policy findPolicy(HttpServletRequest request, String origin)
+    {
+        if (policies.isEmpty())
+            return defaultPolicy;
+        String path = URIUtil.addPaths(request.getServletPath(), request.getPathInfo());
+        for (Policy policy : policies)
+        {
+            if (policy.appliesTo(path, origin))
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Cross-origin request to {} with origin {} uses policy {}", request.getRequestURI(), origin, policy.name);
+                return policy;
+            }
+        }
+        return defaultPolicy;
+    }
+
     protected boolean isEnabled(HttpServletRequest request)
     {
         // WebSocket clients such as Chrome 5 implement a version of the WebSocket
@@ -342,7 +350,61 @@ public class CrossOriginFilter implements Filter
         return true;
     }
 
-    private boolean originMatches(Set<String> allowedOrigins, List<Pattern> allowedOriginPatterns, String originList)
+    /**
+     * <p>Returns whether the given origin is allowed to access the resource.</p>
+     * <p>The default implementation matches the origin against the allowed
+     * origins of the policy that applies to the request; subclasses may
+     * override this method to validate origins dynamically.</p>
+     *
+     * @param request the cross-origin request
+     * @param policyName the name of the policy that applies to the request,
+     * or null if the non-prefixed parameters apply
+     * @param origin the value of the Origin header
+     * @return whether the origin is allowed to access the resource
+     */
+    protected boolean isOriginAllowed(HttpServletRequest request, String policyName, String origin)
+    {
+        Policy policy = defaultPolicy;
+        for (Policy p : policies)
+        {
+            if (p.name.equals(policyName))
+            {
+                policy = p;
+                break;
+            }
+        }
+        return policy.anyOriginAllowed || originMatches(policy.allowedOrigins, policy.allowedOriginPatterns, origin);
+    }
+
+    private static boolean generateAllowedOrigins(Set<String> allowedOriginStore, List<Pattern> allowedOriginPatternStore, String allowedOriginsConfig, String defaultOrigin)
+    {
+        if (allowedOriginsConfig == null)
+            allowedOriginsConfig = defaultOrigin;
+        String[] allowedOrigins = StringUtil.csvSplit(allowedOriginsConfig);
+        for (String allowedOrigin : allowedOrigins)
+        {
+            if (allowedOrigin.length() > 0)
+            {
+                if (ANY_ORIGIN.equals(allowedOrigin))
+                {
+                    allowedOriginStore.clear();
+                    allowedOriginPatternStore.clear();
+                    return true;
+                }
+                else if (allowedOrigin.contains("*"))
+                {
+                    allowedOriginPatternStore.add(Pattern.compile(parseAllowedWildcardOriginToRegex(allowedOrigin)));
+                }
+                else
+                {
+                    allowedOriginStore.add(allowedOrigin);
+                }
+            }
+        }
+        return false;
+    }
+
+    private static boolean originMatches(Set<String> allowedOrigins, List<Pattern> allowedOriginPatterns, String originList)
     {
         if (originList.trim().length() == 0)
             return false;
@@ -365,7 +427,7 @@ public class CrossOriginFilter implements Filter
         return false;
     }
 
-    private String parseAllowedWildcardOriginToRegex(String allowedOrigin)
+    private static String parseAllowedWildcardOriginToRegex(String allowedOrigin)
     {
         String regex = StringUtil.replace(allowedOrigin, ".", "\\.");
         return StringUtil.replace(regex, "*", ".*"); // we want to be greedy here to match multiple subdomains, thus we use .*
@@ -395,49 +457,7 @@ public class CrossOriginFilter implements Filter
         return true;
     }
 
-    private void handleSimpleResponse(HttpServletRequest request, HttpServletResponse response, String origin)
-    {
-        response.setHeader(ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, origin);
-        if (allowCredentials)
-            response.setHeader(ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER, "true");
-        if (!exposedHeaders.isEmpty())
-            response.setHeader(ACCESS_CONTROL_EXPOSE_HEADERS_HEADER, commify(exposedHeaders));
-    }
-
-    private void handlePreflightResponse(HttpServletRequest request, HttpServletResponse response, String origin)
-    {
-        boolean methodAllowed = isMethodAllowed(request);
-
-        if (!methodAllowed)
-            return;
-        List<String> headersRequested = getAccessControlRequestHeaders(request);
-        boolean headersAllowed = areHeadersAllowed(headersRequested);
-        if (!headersAllowed)
-            return;
-        response.setHeader(ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, origin);
-        if (allowCredentials)
-            response.setHeader(ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER, "true");
-        if (preflightMaxAge > 0)
-            response.setHeader(ACCESS_CONTROL_MAX_AGE_HEADER, String.valueOf(preflightMaxAge));
-        response.setHeader(ACCESS_CONTROL_ALLOW_METHODS_HEADER, commify(allowedMethods));
-        if (anyHeadersAllowed)
-            response.setHeader(ACCESS_CONTROL_ALLOW_HEADERS_HEADER, commify(headersRequested));
-        else
-            response.setHeader(ACCESS_CONTROL_ALLOW_HEADERS_HEADER, commify(allowedHeaders));
-    }
-
-    private boolean isMethodAllowed(HttpServletRequest request)
-    {
-        String accessControlRequestMethod = request.getHeader(ACCESS_CONTROL_REQUEST_METHOD_HEADER);
-        LOG.debug("{} is {}", ACCESS_CONTROL_REQUEST_METHOD_HEADER, accessControlRequestMethod);
-        boolean result = false;
-        if (accessControlRequestMethod != null)
-            result = allowedMethods.contains(accessControlRequestMethod);
-        LOG.debug("Method {} is" + (result ? "" : " not") + " among allowed methods {}", accessControlRequestMethod, allowedMethods);
-        return result;
-    }
-
-    private List<String> getAccessControlRequestHeaders(HttpServletRequest request)
+    private static List<String> getAccessControlRequestHeaders(HttpServletRequest request)
     {
         String accessControlRequestHeaders = request.getHeader(ACCESS_CONTROL_REQUEST_HEADERS_HEADER);
         LOG.debug("{} is {}", ACCESS_CONTROL_REQUEST_HEADERS_HEADER, accessControlRequestHeaders);
@@ -455,37 +475,7 @@ public class CrossOriginFilter implements Filter
         return requestedHeaders;
     }
 
-    private boolean areHeadersAllowed(List<String> requestedHeaders)
-    {
-        if (anyHeadersAllowed)
-        {
-            LOG.debug("Any header is allowed");
-            return true;
-        }
-
-        boolean result = true;
-        for (String requestedHeader : requestedHeaders)
-        {
-            boolean headerAllowed = false;
-            for (String allowedHeader : allowedHeaders)
-            {
-                if (requestedHeader.equalsIgnoreCase(allowedHeader.trim()))
-                {
-                    headerAllowed = true;
-                    break;
-                }
-            }
-            if (!headerAllowed)
-            {
-                result = false;
-                break;
-            }
-        }
-        LOG.debug("Headers [{}] are" + (result ? "" : " not") + " among allowed headers {}", requestedHeaders, allowedHeaders);
-        return result;
-    }
-
-    private String commify(List<String> strings)
+    private static String commify(List<String> strings)
     {
         StringBuilder builder = new StringBuilder();
         for (int i = 0; i < strings.size(); ++i)
@@ -501,15 +491,220 @@ public class CrossOriginFilter implements Filter
     @Override
     public void destroy()
     {
-        anyOriginAllowed = false;
-        anyTimingOriginAllowed = false;
-        allowedOrigins.clear();
-        allowedOriginPatterns.clear();
-        allowedTimingOrigins.clear();
-        allowedTimingOriginPatterns.clear();
-        allowedMethods.clear();
-        allowedHeaders.clear();
-        preflightMaxAge = 0;
-        allowCredentials = false;
+        policies.clear();
+        defaultPolicy = null;
+    }
+
+    /**
+     * <p>A cross-origin policy, configured from the filter parameters.</p>
+     */
+    private static class Policy
+    {
+        private final String name;
+        private final Set<String> allowedOrigins = new HashSet<String>();
+        private final List<Pattern> allowedOriginPatterns = new ArrayList<Pattern>();
+        private final Set<String> allowedTimingOrigins = new HashSet<String>();
+        private final List<Pattern> allowedTimingOriginPatterns = new ArrayList<Pattern>();
+        private final List<String> allowedMethods = new ArrayList<String>();
+        private final List<String> allowedHeaders = new ArrayList<String>();
+        private final List<String> exposedHeaders = new ArrayList<String>();
+        private final List<ServletPathSpec> pathSpecs = new ArrayList<>();
+        private final Set<String> scopeOrigins = new HashSet<>();
+        private final List<Pattern> scopeOriginPatterns = new ArrayList<>();
+        private boolean anyOriginAllowed;
+        private boolean anyTimingOriginAllowed;
+        private boolean anyHeadersAllowed;
+        private boolean anyScopeOrigin = true;
+        private int preflightMaxAge;
+        private boolean allowCredentials;
+        private boolean chainPreflight;
+        private boolean allowPrivateNetwork;
+        private String preflightCacheControl;
+
+        private Policy(String name, Function<String, String> config)
+        {
+            this.name = name;
+
+            String allowedOriginsConfig = config.apply(ALLOWED_ORIGINS_PARAM);
+            String allowedTimingOriginsConfig = config.apply(ALLOWED_TIMING_ORIGINS_PARAM);
+
+            anyOriginAllowed = generateAllowedOrigins(allowedOrigins, allowedOriginPatterns, allowedOriginsConfig, DEFAULT_ALLOWED_ORIGINS);
+            anyTimingOriginAllowed = generateAllowedOrigins(allowedTimingOrigins, allowedTimingOriginPatterns, allowedTimingOriginsConfig, DEFAULT_ALLOWED_TIMING_ORIGINS);
+
+            String allowedMethodsConfig = config.apply(ALLOWED_METHODS_PARAM);
+            if (allowedMethodsConfig == null)
+                allowedMethods.addAll(DEFAULT_ALLOWED_METHODS);
+            else
+                allowedMethods.addAll(Arrays.asList(StringUtil.csvSplit(allowedMethodsConfig)));
+
+            String allowedHeadersConfig = config.apply(ALLOWED_HEADERS_PARAM);
+            if (allowedHeadersConfig == null)
+                allowedHeaders.addAll(DEFAULT_ALLOWED_HEADERS);
+            else if ("*".equals(allowedHeadersConfig))
+                anyHeadersAllowed = true;
+            else
+                allowedHeaders.addAll(Arrays.asList(StringUtil.csvSplit(allowedHeadersConfig)));
+
+            String preflightMaxAgeConfig = config.apply(PREFLIGHT_MAX_AGE_PARAM);
+            if (preflightMaxAgeConfig == null)
+                preflightMaxAgeConfig = "1800"; // Default is 30 minutes
+            try
+            {
+                preflightMaxAge = Integer.parseInt(preflightMaxAgeConfig);
+            }
+            catch (NumberFormatException x)
+            {
+                LOG.info("Cross-origin filter, could not parse '{}' parameter as integer: {}", PREFLIGHT_MAX_AGE_PARAM, preflightMaxAgeConfig);
+            }
+
+            String allowedCredentialsConfig = config.apply(ALLOW_CREDENTIALS_PARAM);
+            if (allowedCredentialsConfig == null)
+                allowedCredentialsConfig = "true";
+            allowCredentials = Boolean.parseBoolean(allowedCredentialsConfig);
+
+            String exposedHeadersConfig = config.apply(EXPOSED_HEADERS_PARAM);
+            if (exposedHeadersConfig == null)
+                exposedHeadersConfig = "";
+            exposedHeaders.addAll(Arrays.asList(StringUtil.csvSplit(exposedHeadersConfig)));
+
+            String chainPreflightConfig = config.apply(OLD_CHAIN_PREFLIGHT_PARAM);
+            if (chainPreflightConfig != null)
+                LOG.warn("DEPRECATED CONFIGURATION: Use {} instead of {}", CHAIN_PREFLIGHT_PARAM, OLD_CHAIN_PREFLIGHT_PARAM);
+            else
+                chainPreflightConfig = config.apply(CHAIN_PREFLIGHT_PARAM);
+            if (chainPreflightConfig == null)
+                chainPreflightConfig = "true";
+            chainPreflight = Boolean.parseBoolean(chainPreflightConfig);
+
+            String allowPrivateNetworkConfig = config.apply(ALLOW_PRIVATE_NETWORK_PARAM);
+            allowPrivateNetwork = Boolean.parseBoolean(allowPrivateNetworkConfig);
+
+            String preflightCacheControlConfig = config.apply(PREFLIGHT_CACHE_CONTROL_PARAM);
+            preflightCacheControl = preflightCacheControlConfig == null ? "" : preflightCacheControlConfig.trim();
+
+            if (LOG.isDebugEnabled())
+            {
+                LOG.debug("Cross-origin filter configuration" + (name == null ? "" : " for policy " + name) + ": " +
+                    ALLOWED_ORIGINS_PARAM + " = " + allowedOriginsConfig + ", " +
+                    ALLOWED_TIMING_ORIGINS_PARAM + " = " + allowedTimingOriginsConfig + ", " +
+                    ALLOWED_METHODS_PARAM + " = " + allowedMethodsConfig + ", " +
+                    ALLOWED_HEADERS_PARAM + " = " + allowedHeadersConfig + ", " +
+                    PREFLIGHT_MAX_AGE_PARAM + " = " + preflightMaxAgeConfig + ", " +
+                    ALLOW_CREDENTIALS_PARAM + " = " + allowedCredentialsConfig + "," +
+                    EXPOSED_HEADERS_PARAM + " = " + exposedHeadersConfig + "," +
+                    CHAIN_PREFLIGHT_PARAM + " = " + chainPreflightConfig + "," +
+                    ALLOW_PRIVATE_NETWORK_PARAM + " = " + allowPrivateNetworkConfig + "," +
+                    PREFLIGHT_CACHE_CONTROL_PARAM + " = " + preflightCacheControlConfig
+                );
+            }
+        }
+
+        private void configureScope(String pathsConfig, String originsConfig)
+        {
+            if (pathsConfig != null)
+            {
+                for (String path : StringUtil.csvSplit(pathsConfig))
+                {
+                    if (!path.isEmpty())
+                        pathSpecs.add(new ServletPathSpec(path));
+                }
+            }
+            if (originsConfig != null)
+                anyScopeOrigin = generateAllowedOrigins(scopeOrigins, scopeOriginPatterns, originsConfig, ANY_ORIGIN);
+        }
+
+        private boolean appliesTo(String path, String origin)
+        {
+            if (!pathSpecs.isEmpty() && pathSpecs.stream().noneMatch(pathSpec -> pathSpec.matches(path)))
+                return false;
+            return anyScopeOrigin || originMatches(scopeOrigins, scopeOriginPatterns, origin);
+        }
+
+        private void handleSimpleResponse(HttpServletRequest request, HttpServletResponse response, String origin)
+        {
+            response.setHeader(ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, origin);
+            if (allowCredentials)
+                response.setHeader(ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER, "true");
+            if (!exposedHeaders.isEmpty())
+                response.setHeader(ACCESS_CONTROL_EXPOSE_HEADERS_HEADER, commify(exposedHeaders));
+        }
+
+        private boolean handlePreflightResponse(HttpServletRequest request, HttpServletResponse response, String origin)
+        {
+            boolean methodAllowed = isMethodAllowed(request);
+
+            if (!methodAllowed)
+                return false;
+            List<String> headersRequested = getAccessControlRequestHeaders(request);
+            boolean headersAllowed = areHeadersAllowed(headersRequested);
+            if (!headersAllowed)
+                return false;
+            if (!isPrivateNetworkAllowed(request))
+                return false;
+            response.setHeader(ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, origin);
+            if (allowCredentials)
+                response.setHeader(ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER, "true");
+            if (preflightMaxAge > 0)
+                response.setHeader(ACCESS_CONTROL_MAX_AGE_HEADER, String.valueOf(preflightMaxAge));
+            response.setHeader(ACCESS_CONTROL_ALLOW_METHODS_HEADER, commify(allowedMethods));
+            if (anyHeadersAllowed)
+                response.setHeader(ACCESS_CONTROL_ALLOW_HEADERS_HEADER, commify(headersRequested));
+            else
+                response.setHeader(ACCESS_CONTROL_ALLOW_HEADERS_HEADER, commify(allowedHeaders));
+            if (allowPrivateNetwork && request.getHeader(ACCESS_CONTROL_REQUEST_PRIVATE_NETWORK_HEADER) != null)
+                response.setHeader(ACCESS_CONTROL_ALLOW_PRIVATE_NETWORK_HEADER, "true");
+            return true;
+        }
+
+        private boolean isMethodAllowed(HttpServletRequest request)
+        {
+            String accessControlRequestMethod = request.getHeader(ACCESS_CONTROL_REQUEST_METHOD_HEADER);
+            LOG.debug("{} is {}", ACCESS_CONTROL_REQUEST_METHOD_HEADER, accessControlRequestMethod);
+            boolean result = false;
+            if (accessControlRequestMethod != null)
+                result = allowedMethods.contains(accessControlRequestMethod);
+            LOG.debug("Method {} is" + (result ? "" : " not") + " among allowed methods {}", accessControlRequestMethod, allowedMethods);
+            return result;
+        }
+
+        private boolean areHeadersAllowed(List<String> requestedHeaders)
+        {
+            if (anyHeadersAllowed)
+            {
+                LOG.debug("Any header is allowed");
+                return true;
+            }
+
+            boolean result = true;
+            for (String requestedHeader : requestedHeaders)
+            {
+                boolean headerAllowed = false;
+                for (String allowedHeader : allowedHeaders)
+                {
+                    if (requestedHeader.equalsIgnoreCase(allowedHeader.trim()))
+                    {
+                        headerAllowed = true;
+                        break;
+                    }
+                }
+                if (!headerAllowed)
+                {
+                    result = false;
+                    break;
+                }
+            }
+            LOG.debug("Headers [{}] are" + (result ? "" : " not") + " among allowed headers {}", requestedHeaders, allowedHeaders);
+            return result;
+        }
+
+        private boolean isPrivateNetworkAllowed(HttpServletRequest request)
+        {
+            String privateNetwork = request.getHeader(ACCESS_CONTROL_REQUEST_PRIVATE_NETWORK_HEADER);
+            if (privateNetwork == null)
+                return true;
+            boolean result = allowPrivateNetwork && "true".equalsIgnoreCase(privateNetwork.trim());
+            LOG.debug("Private network access is" + (result ? "" : " not") + " allowed");
+            return result;
+        }
     }
 }
@@ -14,10 +14,17 @@
 package org.eclipse.jetty.servlets;
 
 import java.io.IOException;
+import java.util.Arrays;
+import java.util.EventListener;
+import java.util.List;
 import java.util.Queue;
 import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.Semaphore;
 import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.function.Predicate;
 import javax.servlet.AsyncContext;
 import javax.servlet.AsyncEvent;
 import javax.servlet.AsyncListener;
@@ -32,9 +39,14 @@ import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 import javax.servlet.http.HttpSession;
 
+import org.eclipse.jetty.http.pathmap.ServletPathSpec;
 import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.util.Loader;
+import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
@@ -66,6 +78,23 @@ import org.slf4j.LoggerFactory;
  * avoided if the semaphore is shortly available.  If the semaphore cannot be obtained, the request will be suspended
  * for the default suspend period of the container or the valued set as the "suspendMs" init parameter.
  * <p>
+ * Priorities may also be computed by a {@link Classifier}, either set with {@link #setClassifier(Classifier)},
+ * named by the "classifier" init parameter, or built from the rules of the "priorityRules" init parameter
+ * (see {@link RuleClassifier#parse(String)}).
+ * <p>
+ * By default the suspended requests with the highest priority are always resumed first, so a sustained load of
+ * high priority requests may starve the lower priorities.  If the "priorityWeights" init parameter is set to a
+ * comma separated list of weights indexed by priority (missing entries default to 1), suspended requests are instead
+ * resumed in weighted fair order: each priority with suspended requests is resumed in proportion to its weight.
+ * The "priorityMaxQueued" and "prioritySuspendMs" init parameters are also comma separated lists indexed by priority:
+ * the former limits the number of requests suspended at each priority, requests beyond it being rejected immediately,
+ * and the latter overrides "suspendMs" for each priority.  Negative or missing entries mean no limit and the default
+ * suspend period respectively.
+ * <p>
+ * Registered {@link StarvationListener}s are notified when a suspended request times out before being resumed or,
+ * if the "starvationMs" init parameter is set, when it is resumed after having waited longer than that, so that
+ * applications sharing the server may shed or preempt less important work.
+ * <p>
  * If the "managedAttr" init parameter is set to true, then this servlet is set as a {@link ServletContext} attribute with the
  * filter name as the attribute name.  This allows context external mechanism (eg JMX via {@link ContextHandler#MANAGED_ATTRIBUTES}) to
  * manage the configuration of the filter.
@@ -85,15 +114,25 @@ public class QoSFilter implements Filter
     static final String MAX_PRIORITY_INIT_PARAM = "maxPriority";
     static final String MAX_WAIT_INIT_PARAM = "waitMs";
     static final String SUSPEND_INIT_PARAM = "suspendMs";
+    static final String CLASSIFIER_INIT_PARAM = "classifier";
+    static final String PRIORITY_RULES_INIT_PARAM = "priorityRules";
+    static final String PRIORITY_WEIGHTS_INIT_PARAM = "priorityWeights";
+    static final String PRIORITY_MAX_QUEUED_INIT_PARAM = "priorityMaxQueued";
+    static final String PRIORITY_SUSPEND_INIT_PARAM = "prioritySuspendMs";
+    static final String STARVATION_INIT_PARAM = "starvationMs";
 
     private final String _suspended = "QoSFilter@" + Integer.toHexString(hashCode()) + ".SUSPENDED";
     private final String _resumed = "QoSFilter@" + Integer.toHexString(hashCode()) + ".RESUMED";
+    private final String _queuedAt = "QoSFilter@" + Integer.toHexString(hashCode()) + ".QUEUED_AT";
+    private final List<StarvationListener> _starvationListeners = new CopyOnWriteArrayList<>();
     private long _waitMs;
     private long _suspendMs;
+    private long _starvationMs;
     private int _maxRequests;
+    private boolean _weighted;
+    private volatile Classifier _classifier;
     private Semaphore _passes;
-    private Queue<AsyncContext>[] _queues;
-    private AsyncListener[] _listeners;
+    private PriorityClass[] _classes;
 
     @Override
     public void init(FilterConfig filterConfig)
@@ -101,12 +140,16 @@ public class QoSFilter implements Filter
         int maxPriority = __DEFAULT_MAX_PRIORITY;
         if (filterConfig.getInitParameter(MAX_PRIORITY_INIT_PARAM) != null)
             maxPriority = Integer.parseInt(filterConfig.getInitParameter(MAX_PRIORITY_INIT_PARAM));
-        _queues = new Queue[maxPriority + 1];
-        _listeners = new AsyncListener[_queues.length];
-        for (int p = 0; p < _queues.length; ++p)
+        long[] weights = getPriorityValues(filterConfig, PRIORITY_WEIGHTS_INIT_PARAM, maxPriority + 1, 1);
+        long[] maxQueued = getPriorityValues(filterConfig, PRIORITY_MAX_QUEUED_INIT_PARAM, maxPriority + 1, -1);
+        long[] suspends = getPriorityValues(filterConfig, PRIORITY_SUSPEND_INIT_PARAM, maxPriority + 1, -1);
+        _weighted = filterConfig.getInitParameter(PRIORITY_WEIGHTS_INIT_PARAM) != null;
+        _classes = new PriorityClass[maxPriority + 1];
+        for (int p = 0; p < _classes.length; ++p)
         {
-            _queues[p] = new ConcurrentLinkedQueue<>();
-            _listeners[p] = new QoSAsyncListener(p);
+            if (weights[p] <= 0)
+                throw new IllegalArgumentException("Invalid weight " + weights[p] + " for priority " + p);
+            _classes[p] = new PriorityClass(p, weights[p], (int)maxQueued[p], suspends[p]);
         }
 
         int maxRequests = __DEFAULT_PASSES;
@@ -125,6 +168,28 @@ public class QoSFilter implements Filter
             suspend = Integer.parseInt(filterConfig.getInitParameter(SUSPEND_INIT_PARAM));
         _suspendMs = suspend;
 
+        long starvation = __DEFAULT_TIMEOUT_MS;
+        if (filterConfig.getInitParameter(STARVATION_INIT_PARAM) != null)
+            starvation = Long.parseLong(filterConfig.getInitParameter(STARVATION_INIT_PARAM));
+        _starvationMs = starvation;
+
+        String classifier = filterConfig.getInitParameter(CLASSIFIER_INIT_PARAM);
+        if (classifier != null)
+        {
+            try
+            {
+                setClassifier((Classifier)Loader.loadClass(classifier).getDeclaredConstructor().newInstance());
+            }
+            catch (Exception x)
+            {
+                throw new IllegalArgumentException("Cannot create classifier " + classifier, x);
+            }
+        }
+        else if (filterConfig.getInitParameter(PRIORITY_RULES_INIT_PARAM) != null)
+        {
+            setClassifier(RuleClassifier.parse(filterConfig.getInitParameter(PRIORITY_RULES_INIT_PARAM)));
+        }
+
         ServletContext context = filterConfig.getServletContext();
         if (context != null && Boolean.parseBoolean(filterConfig.getInitParameter(MANAGED_ATTR_INIT_PARAM)))
             context.setAttribute(filterConfig.getFilterName(), this);
@@ -148,16 +213,25 @@ public class QoSFilter implements Filter
                 }
                 else
                 {
+                    int priority = Math.max(0, Math.min(getPriority(request), _classes.length - 1));
+                    PriorityClass priorityClass = _classes[priority];
+                    if (priorityClass.isFull())
+                    {
+                        if (LOG.isDebugEnabled())
+                            LOG.debug("Rejected {}, too many requests suspended at priority {}", request, priority);
+                        ((HttpServletResponse)response).sendError(HttpServletResponse.SC_SERVICE_UNAVAILABLE);
+                        return;
+                    }
                     request.setAttribute(_suspended, Boolean.TRUE);
-                    int priority = getPriority(request);
+                    request.setAttribute(_queuedAt, System.nanoTime());
                     AsyncContext asyncContext = request.startAsync();
-                    long suspendMs = getSuspendMs();
+                    long suspendMs = priorityClass.getSuspendMs();
                     if (suspendMs > 0)
                         asyncContext.setTimeout(suspendMs);
-                    asyncContext.addListener(_listeners[priority]);
-                    _queues[priority].add(asyncContext);
+                    asyncContext.addListener(priorityClass);
+                    priorityClass.offer(asyncContext);
                     if (LOG.isDebugEnabled())
-                        LOG.debug("Suspended {}", request);
+                        LOG.debug("Suspended {} at priority {}", request, priority);
                     return;
                 }
             }
@@ -213,9 +287,12 @@ public class QoSFilter implements Filter
             {
                 _passes.release();
 
-                for (int p = _queues.length - 1; p >= 0; --p)
+                while (true)
                 {
-                    AsyncContext asyncContext = _queues[p].poll();
+                    PriorityClass priorityClass = select();
+                    if (priorityClass == null)
+                        break;
+                    AsyncContext asyncContext = priorityClass.poll();
                     if (asyncContext != null)
                     {
                         ServletRequest candidate = asyncContext.getRequest();
@@ -223,9 +300,12 @@ public class QoSFilter implements Filter
                         if (Boolean.TRUE.equals(suspended))
                         {
                             try
-                            {  
+                            {
+                                long waitedMs = getWaitedMs(candidate);
                                 candidate.setAttribute(_resumed, Boolean.TRUE);
                                 asyncContext.dispatch();
+                                if (_starvationMs > 0 && waitedMs > _starvationMs)
+                                    notifyStarved(priorityClass, candidate, waitedMs);
                                 break;
                             }
                             catch (IllegalStateException x)
@@ -240,10 +320,94 @@ public class QoSFilter implements Filter
         }
     }
 
+    /**
+     * Selects the priority of the next suspended request to resume.
+     *
+     * @return the priority class to poll, or null if no request is suspended
+     */
+    private PriorityClass select()
+    {
+        if (!_weighted)
+        {
+            for (int p = _classes.length - 1; p >= 0; --p)
+            {
+                if (!_classes[p].isEmpty())
+                    return _classes[p];
+            }
+            return null;
+        }
+
+        // Smooth weighted round robin across the priorities that have suspended requests,
+        // with ties going to the higher priority.
+        synchronized (_classes)
+        {
+            PriorityClass selected = null;
+            long total = 0;
+            for (int p = _classes.length - 1; p >= 0; --p)
+            {
+                PriorityClass priorityClass = _classes[p];
+                if (priorityClass.isEmpty())
+                {
+                    priorityClass.credit = 0;
+                    continue;
+                }
+                priorityClass.credit += priorityClass.weight;
+                total += priorityClass.weight;
+                if (selected == null || priorityClass.credit > selected.credit)
+                    selected = priorityClass;
+            }
+            if (selected != null)
+                selected.credit -= total;
+            return selected;
+        }
+    }
+
+    private long getWaitedMs(ServletRequest request)
+    {
+        Long queuedAt = (Long)request.getAttribute(_queuedAt);
+        return queuedAt == null ? 0 : TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - queuedAt);
+    }
+
+    private void notifyStarved(PriorityClass priorityClass, ServletRequest request, long waitedMs)
+    {
+        priorityClass.starved.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Starved {} at priority {} after {} ms", request, priorityClass.priority, waitedMs);
+        for (StarvationListener listener : _starvationListeners)
+        {
+            try
+            {
+                listener.onStarved(this, request, priorityClass.priority, waitedMs);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    private static long[] getPriorityValues(FilterConfig filterConfig, String name, int length, long defaultValue)
+    {
+        long[] values = new long[length];
+        Arrays.fill(values, defaultValue);
+        String parameter = filterConfig.getInitParameter(name);
+        if (parameter != null)
+        {
+            String[] split = StringUtil.csvSplit(parameter);
+            for (int p = 0; p < split.length && p < length; ++p)
+            {
+                if (!split[p].isEmpty())
+                    values[p] = Long.parseLong(split[p]);
+            }
+        }
+        return values;
+    }
+
     /**
      * Computes the request priority.
      * <p>
-     * The default implementation assigns the following priorities:
+     * If a {@link Classifier} is set and it classifies the request, its priority is returned.
+     * Otherwise the default implementation assigns the following priorities:
      * <ul>
      * <li> 2 - for an authenticated request
      * <li> 1 - for a request with valid / non new session
@@ -257,6 +421,13 @@ public class QoSFilter implements Filter
     protected int getPriority(ServletRequest request)
     {
         HttpServletRequest baseRequest = (HttpServletRequest)request;
+        Classifier classifier = _classifier;
+        if (classifier != null)
+        {
+            int priority = classifier.classify(baseRequest);
+            if (priority >= 0)
+                return priority;
+        }
         if (baseRequest.getUserPrincipal() != null)
         {
             return 2;
@@ -351,13 +522,126 @@ public class QoSFilter implements Filter
         LOG.warn("Setter ignored: use maxRequests init-param for QoSFilter instead");
     }
 
-    private class QoSAsyncListener implements AsyncListener
+    /**
+     * Get whether suspended requests are resumed in weighted fair order
+     * rather than in strict priority order.
+     *
+     * @return whether the "priorityWeights" init-param is set
+     */
+    @ManagedAttribute("whether suspended requests are resumed in weighted fair order rather than strict priority order")
+    public boolean isWeighted()
+    {
+        return _weighted;
+    }
+
+    /**
+     * Get the amount of time (in milliseconds) after which a resumed request
+     * is reported to the {@link StarvationListener}s as starved.
+     *
+     * @return starvation time (in milliseconds), or a non-positive value if disabled
+     */
+    @ManagedAttribute("amount of time a suspended request may wait before being reported as starved (in ms)")
+    public long getStarvationMs()
+    {
+        return _starvationMs;
+    }
+
+    /**
+     * @param priority the request priority
+     * @return the number of requests currently suspended at the given priority
+     */
+    @ManagedOperation(value = "number of requests currently suspended at the given priority", impact = "INFO")
+    public int getQueueSize(@Name("priority") int priority)
+    {
+        return _classes[priority].size.get();
+    }
+
+    /**
+     * @param priority the request priority
+     * @return the number of starvation events reported for the given priority
+     */
+    @ManagedOperation(value = "number of starvation events reported for the given priority", impact = "INFO")
+    public long getStarvedCount(@Name("priority") int priority)
+    {
+        return _classes[priority].starved.sum();
+    }
+
+    /**
+     * @return the classifier used by {@link #getPriority(ServletRequest)}, or null
+     */
+    public Classifier getClassifier()
+    {
+        return _classifier;
+    }
+
+    /**
+     * @param classifier the classifier used by {@link #getPriority(ServletRequest)}, or null
+     */
+    public void setClassifier(Classifier classifier)
+    {
+        _classifier = classifier;
+    }
+
+    public void addStarvationListener(StarvationListener listener)
     {
+        _starvationListeners.add(listener);
+    }
+
+    public boolean removeStarvationListener(StarvationListener listener)
+    {
+        return _starvationListeners.remove(listener);
+    }
+
+    /**
+     * The queue of requests suspended at a given priority.
+     */
+    private class PriorityClass implements AsyncListener
+    {
+        private final Queue<AsyncContext> queue = new ConcurrentLinkedQueue<>();
+        private final AtomicInteger size = new AtomicInteger();
+        private final LongAdder starved = new LongAdder();
         private final int priority;
+        private final long weight;
+        private final int maxQueued;
+        private final long suspendMs;
+        // Guarded by _classes.
+        private long credit;
 
-        public QoSAsyncListener(int priority)
+        private PriorityClass(int priority, long weight, int maxQueued, long suspendMs)
         {
             this.priority = priority;
+            this.weight = weight;
+            this.maxQueued = maxQueued;
+            this.suspendMs = suspendMs;
+        }
+
+        private long getSuspendMs()
+        {
+            return suspendMs > 0 ? suspendMs : QoSFilter.this.getSuspendMs();
+        }
+
+        private boolean isEmpty()
+        {
+            return queue.isEmpty();
+        }
+
+        private boolean isFull()
+        {
+            return maxQueued >= 0 && size.get() >= maxQueued;
+        }
+
+        private void offer(AsyncContext asyncContext)
+        {
+            size.incrementAndGet();
+            queue.add(asyncContext);
+        }
+
+        private AsyncContext poll()
+        {
+            AsyncContext asyncContext = queue.poll();
+            if (asyncContext != null)
+                size.decrementAndGet();
+            return asyncContext;
         }
 
         @Override
@@ -376,7 +660,11 @@ public class QoSFilter implements Filter
             // Remove before it's redispatched, so it won't be
             // redispatched again at the end of the filtering.
             AsyncContext asyncContext = event.getAsyncContext();
-            _queues[priority].remove(asyncContext);
+            if (queue.remove(asyncContext))
+            {
+                size.decrementAndGet();
+                notifyStarved(this, asyncContext.getRequest(), getWaitedMs(asyncContext.getRequest()));
+            }
             ((HttpServletResponse)event.getSuppliedResponse()).sendError(HttpServletResponse.SC_SERVICE_UNAVAILABLE);
             asyncContext.complete();
         }
@@ -386,4 +674,170 @@ public class QoSFilter implements Filter
         {
         }
     }
+
+    /**
+     * Computes the priority of a request.
+     */
+    public interface Classifier
+    {
+        /**
+         * @param request the incoming request
+         * @return the request priority, or a negative value if this classifier does not apply to the request
+         */
+        int classify(HttpServletRequest request);
+    }
+
+    /**
+     * Listener for suspended requests that are starved.
+     */
+    public interface StarvationListener extends EventListener
+    {
+        /**
+         * Invoked when a suspended request times out before being resumed, or when it is
+         * resumed after having waited longer than {@link #getStarvationMs()}.
+         *
+         * @param filter the {@link QoSFilter} that this event occurred on
+         * @param request the starved request
+         * @param priority the priority of the starved request
+         * @param waitedMs how long the request has been suspended (in milliseconds)
+         */
+        void onStarved(QoSFilter filter, ServletRequest request, int priority, long waitedMs);
+    }
+
+    /**
+     * A {@link Classifier} that returns the priority of the first of its rules matching the request.
+     */
+    public static class RuleClassifier implements Classifier
+    {
+        private final List<Rule> _rules = new CopyOnWriteArrayList<>();
+
+        /**
+         * Parses a comma separated list of rules of the form {@code type:match=priority},
+         * where the type is one of:
+         * <ul>
+         * <li>{@code path} - the match is a servlet path spec for the path within the context, eg {@code path:/api/*=5}</li>
+         * <li>{@code role} - the match is a role of the authenticated user, eg {@code role:admin=9}</li>
+         * <li>{@code header} - the match is a header name, optionally followed by a colon and its value
+         * (compared case insensitively), eg {@code header:X-Batch=0} or {@code header:X-Tier:gold=7}</li>
+         * </ul>
+         *
+         * @param rules the rules to parse
+         * @return a new classifier with the given rules
+         */
+        public static RuleClassifier parse(String rules)
+        {
+            RuleClassifier classifier = new RuleClassifier();
+            for (String rule : StringUtil.csvSplit(rules))
+            {
+                int colon = rule.indexOf(':');
+                int equals = rule.lastIndexOf('=');
+                if (colon < 0 || equals < colon)
+                    throw new IllegalArgumentException("Invalid priority rule " + rule);
+                String type = rule.substring(0, colon).trim();
+                String match = rule.substring(colon + 1, equals).trim();
+                int priority = Integer.parseInt(rule.substring(equals + 1).trim());
+                switch (type)
+                {
+                    case "path":
+                        classifier.addPathRule(match, priority);
+                        break;
+                    case "role":
+                        classifier.addRoleRule(match, priority);
+                        break;
+                    case "header":
+                        int separator = match.indexOf(':');
+                        if (separator < 0)
+                            classifier.addHeaderRule(match, null, priority);
+                        else
+                            classifier.addHeaderRule(match.substring(0, separator).trim(), match.substring(separator + 1).trim(), priority);
+                        break;
+                    default:
+                        throw new IllegalArgumentException("Invalid priority rule type " + rule);
+                }
+            }
+            return classifier;
+        }
+
+        /**
+         * @param pathSpec the servlet path spec matched against the path within the context
+         * @param priority the priority of the matching requests
+         * @return this classifier
+         */
+        public RuleClassifier addPathRule(String pathSpec, int priority)
+        {
+            ServletPathSpec servletPathSpec = new ServletPathSpec(pathSpec);
+            _rules.add(new Rule("path:" + pathSpec, priority, request ->
+            {
+                String path = request.getServletPath();
+                if (request.getPathInfo() != null)
+                    path += request.getPathInfo();
+                return servletPathSpec.matches(path);
+            }));
+            return this;
+        }
+
+        /**
+         * @param role the role of the authenticated user
+         * @param priority the priority of the matching requests
+         * @return this classifier
+         */
+        public RuleClassifier addRoleRule(String role, int priority)
+        {
+            _rules.add(new Rule("role:" + role, priority, request -> request.isUserInRole(role)));
+            return this;
+        }
+
+        /**
+         * @param name the header name
+         * @param value the header value, or null to match any request having the header
+         * @param priority the priority of the matching requests
+         * @return this classifier
+         */
+        public RuleClassifier addHeaderRule(String name, String value, int priority)
+        {
+            String description = "header:" + name + (value == null ? "" : ":" + value);
+            if (value == null)
+                _rules.add(new Rule(description, priority, request -> request.getHeader(name) != null));
+            else
+                _rules.add(new Rule(description, priority, request -> value.equalsIgnoreCase(request.getHeader(name))));
+            return this;
+        }
+
+        @Override
+        public int classify(HttpServletRequest request)
+        {
+            for (Rule rule : _rules)
+            {
+                if (rule.predicate.test(request))
+                    return rule.priority;
+            }
+            return -1;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), _rules);
+        }
+
+        private static class Rule
+        {
+            private final String description;
+            private final int priority;
+            private final Predicate<HttpServletRequest> predicate;
+
+            private Rule(String description, int priority, Predicate<HttpServletRequest> predicate)
+            {
+                this.description = description;
+                this.priority = priority;
+                this.predicate = predicate;
+            }
+
+            @Override
+            public String toString()
+            {
+                return description + "=" + priority;
+            }
+        }
+    }
 }
@@ -633,6 +633,186 @@ public class CrossOriginFilterTest
         assertFalse(latch.await(1, TimeUnit.SECONDS));
     }
 
+    @Test
+    public void testPreflightWithPrivateNetworkAccess() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter());
+        filterHolder.setInitParameter(CrossOriginFilter.ALLOW_PRIVATE_NETWORK_PARAM, "true");
+        filterHolder.setInitParameter(CrossOriginFilter.CHAIN_PREFLIGHT_PARAM, "false");
+        filterHolder.setInitParameter(CrossOriginFilter.PREFLIGHT_CACHE_CONTROL_PARAM, "public, max-age=600");
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(1);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        String request =
+            "OPTIONS / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Connection: close\r\n" +
+                CrossOriginFilter.ACCESS_CONTROL_REQUEST_METHOD_HEADER + ": GET\r\n" +
+                CrossOriginFilter.ACCESS_CONTROL_REQUEST_PRIVATE_NETWORK_HEADER + ": true\r\n" +
+                "Origin: http://localhost\r\n" +
+                "\r\n";
+        String rawResponse = connector.getResponse(request);
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat(response.toString(), response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_PRIVATE_NETWORK_HEADER), is("true"));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER), is("http://localhost"));
+        assertThat(response.toString(), response.get(HttpHeader.CACHE_CONTROL), is("public, max-age=600"));
+        assertFalse(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testPreflightCacheControlNotSetWhenMethodNotAllowed() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter());
+        filterHolder.setInitParameter(CrossOriginFilter.CHAIN_PREFLIGHT_PARAM, "false");
+        filterHolder.setInitParameter(CrossOriginFilter.PREFLIGHT_CACHE_CONTROL_PARAM, "public, max-age=600");
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(1);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        String request =
+            "OPTIONS / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Connection: close\r\n" +
+                CrossOriginFilter.ACCESS_CONTROL_REQUEST_METHOD_HEADER + ": DELETE\r\n" +
+                "Origin: http://localhost\r\n" +
+                "\r\n";
+        String rawResponse = connector.getResponse(request);
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat(response.toString(), response.getStatus(), is(HttpStatus.OK_200));
+        Set<String> fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, not(is(in(fieldNames))));
+        assertThat(response.toString(), HttpHeader.CACHE_CONTROL.asString(), not(is(in(fieldNames))));
+        assertFalse(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testPreflightWithPrivateNetworkAccessNotAllowed() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter());
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(1);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        String request =
+            "OPTIONS / HTTP/1.1\r\n" +
+                "Host: localhost\r\n" +
+                "Connection: close\r\n" +
+                CrossOriginFilter.ACCESS_CONTROL_REQUEST_METHOD_HEADER + ": GET\r\n" +
+                CrossOriginFilter.ACCESS_CONTROL_REQUEST_PRIVATE_NETWORK_HEADER + ": true\r\n" +
+                "Origin: http://localhost\r\n" +
+                "\r\n";
+        String rawResponse = connector.getResponse(request);
+        HttpTester.Response response = HttpTester.parseResponse(rawResponse);
+
+        assertThat(response.toString(), response.getStatus(), is(HttpStatus.OK_200));
+        Set<String> fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_PRIVATE_NETWORK_HEADER, not(is(in(fieldNames))));
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, not(is(in(fieldNames))));
+        assertTrue(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testPathScopedPolicy() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter());
+        filterHolder.setInitParameter(CrossOriginFilter.ALLOWED_ORIGINS_PARAM, "http://localhost");
+        filterHolder.setInitParameter(CrossOriginFilter.POLICIES_PARAM, "api");
+        filterHolder.setInitParameter("api." + CrossOriginFilter.POLICY_PATHS_PARAM, "/api/*");
+        filterHolder.setInitParameter("api." + CrossOriginFilter.ALLOWED_ORIGINS_PARAM, "http://*.example.com");
+        filterHolder.setInitParameter("api." + CrossOriginFilter.ALLOW_CREDENTIALS_PARAM, "false");
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(4);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        // The api policy applies to /api/*.
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/api/data", "http://app.example.com")));
+        assertThat(response.toString(), response.getStatus(), is(HttpStatus.OK_200));
+        Set<String> fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER), is("http://app.example.com"));
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER, not(is(in(fieldNames))));
+
+        response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/api/data", "http://localhost")));
+        fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, not(is(in(fieldNames))));
+
+        // The non-prefixed parameters apply to other paths.
+        response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/other", "http://localhost")));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER), is("http://localhost"));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_CREDENTIALS_HEADER), is("true"));
+
+        response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/other", "http://app.example.com")));
+        fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, not(is(in(fieldNames))));
+
+        assertTrue(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testOriginScopedPolicy() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter());
+        filterHolder.setInitParameter(CrossOriginFilter.EXPOSED_HEADERS_PARAM, "Content-Length");
+        filterHolder.setInitParameter(CrossOriginFilter.POLICIES_PARAM, "partner");
+        filterHolder.setInitParameter("partner." + CrossOriginFilter.POLICY_ORIGINS_PARAM, "http://partner.com");
+        filterHolder.setInitParameter("partner." + CrossOriginFilter.EXPOSED_HEADERS_PARAM, "Content-Length,X-Partner");
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(2);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/", "http://partner.com")));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_EXPOSE_HEADERS_HEADER), is("Content-Length,X-Partner"));
+
+        response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/", "http://localhost")));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_EXPOSE_HEADERS_HEADER), is("Content-Length"));
+
+        assertTrue(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testDynamicOriginValidation() throws Exception
+    {
+        FilterHolder filterHolder = new FilterHolder(new CrossOriginFilter()
+        {
+            @Override
+            protected boolean isOriginAllowed(HttpServletRequest request, String policyName, String origin)
+            {
+                return origin.endsWith(".registered.com");
+            }
+        });
+        filterHolder.setInitParameter(CrossOriginFilter.ALLOWED_ORIGINS_PARAM, "http://localhost");
+        context.addFilter(filterHolder, "/*", EnumSet.of(DispatcherType.REQUEST));
+
+        CountDownLatch latch = new CountDownLatch(2);
+        context.addServlet(new ServletHolder(new ResourceServlet(latch)), "/*");
+
+        HttpTester.Response response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/", "http://client.registered.com")));
+        assertThat(response.toString(), response.get(CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER), is("http://client.registered.com"));
+
+        response = HttpTester.parseResponse(connector.getResponse(newSimpleRequest("/", "http://localhost")));
+        Set<String> fieldNames = response.getFieldNamesCollection();
+        assertThat(response.toString(), CrossOriginFilter.ACCESS_CONTROL_ALLOW_ORIGIN_HEADER, not(is(in(fieldNames))));
+
+        assertTrue(latch.await(1, TimeUnit.SECONDS));
+    }
+
+    private static String newSimpleRequest(String path, String origin)
+    {
+        return "GET " + path + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Connection: close\r\n" +
+            "Origin: " + origin + "\r\n" +
+            "\r\n";
+    }
+
     public static class ResourceServlet extends HttpServlet
     {
         private static final long serialVersionUID = 1L;
@@ -16,12 +16,16 @@ package org.eclipse.jetty.servlets;
 import java.io.IOException;
 import java.net.URL;
 import java.util.ArrayList;
+import java.util.Collections;
 import java.util.EnumSet;
 import java.util.List;
+import java.util.concurrent.BlockingQueue;
 import java.util.concurrent.Callable;
+import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.ExecutorService;
 import java.util.concurrent.Executors;
 import java.util.concurrent.Future;
+import java.util.concurrent.LinkedBlockingQueue;
 import java.util.concurrent.TimeUnit;
 import javax.servlet.DispatcherType;
 import javax.servlet.ServletException;
@@ -30,6 +34,7 @@ import javax.servlet.http.HttpServlet;
 import javax.servlet.http.HttpServletRequest;
 import javax.servlet.http.HttpServletResponse;
 
+import org.eclipse.jetty.http.HttpStatus;
 import org.eclipse.jetty.http.HttpTester;
 import org.eclipse.jetty.server.LocalConnector;
 import org.eclipse.jetty.server.Server;
@@ -46,8 +51,11 @@ import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
 import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
 import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertTrue;
 
 public class QoSFilterTest
 {
@@ -66,8 +74,13 @@ public class QoSFilterTest
         server = new Server();
         context = new ServletContextHandler(server, "/context");
         context.addServlet(TestServlet.class, "/test");
+        context.addServlet(BlockingServlet.class, "/block");
+        context.addServlet(RecordingServlet.class, "/record");
         TestServlet.__maxSleepers = 0;
         TestServlet.__sleepers = 0;
+        BlockingServlet.__entered = new CountDownLatch(1);
+        BlockingServlet.__release = new CountDownLatch(1);
+        RecordingServlet.__requests.clear();
 
         _connectors = new LocalConnector[numConnections];
         for (int i = 0; i < _connectors.length; ++i)
@@ -146,6 +159,126 @@ public class QoSFilterTest
         assertEquals(TestServlet.__maxSleepers, maxQos);
     }
 
+    @Test
+    public void testPriorityMaxQueued() throws Exception
+    {
+        QoSFilter filter = new QoSFilter2();
+        FilterHolder holder = new FilterHolder(filter);
+        holder.setAsyncSupported(true);
+        holder.setInitParameter(QoSFilter.MAX_REQUESTS_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.MAX_WAIT_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.PRIORITY_MAX_QUEUED_INIT_PARAM, "1");
+        context.getServletHandler().addFilterWithMapping(holder, "/*", EnumSet.of(DispatcherType.REQUEST, DispatcherType.ASYNC));
+
+        LocalConnector connector = _connectors[0];
+        LocalConnector.LocalEndPoint blocker = connector.executeRequest(newRequest("/block?priority=0"));
+        assertTrue(BlockingServlet.__entered.await(5, TimeUnit.SECONDS));
+
+        LocalConnector.LocalEndPoint queued = connector.executeRequest(newRequest("/record?priority=0&id=queued"));
+        awaitQueueSize(filter, 0, 1);
+
+        // Priority 0 is full, but other priorities are not limited.
+        HttpTester.Response rejected = HttpTester.parseResponse(connector.getResponse(newRequest("/record?priority=0&id=rejected")));
+        assertThat(rejected.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        LocalConnector.LocalEndPoint other = connector.executeRequest(newRequest("/record?priority=1&id=other"));
+        awaitQueueSize(filter, 1, 1);
+
+        BlockingServlet.__release.countDown();
+        assertThat(HttpTester.parseResponse(blocker.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(queued.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(other.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(RecordingServlet.__requests, contains("other", "queued"));
+        assertThat(filter.getQueueSize(0), is(0));
+    }
+
+    @Test
+    public void testWeightedFairQueuing() throws Exception
+    {
+        QoSFilter filter = new QoSFilter2();
+        FilterHolder holder = new FilterHolder(filter);
+        holder.setAsyncSupported(true);
+        holder.setInitParameter(QoSFilter.MAX_REQUESTS_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.MAX_PRIORITY_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.MAX_WAIT_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.PRIORITY_WEIGHTS_INIT_PARAM, "1,3");
+        context.getServletHandler().addFilterWithMapping(holder, "/*", EnumSet.of(DispatcherType.REQUEST, DispatcherType.ASYNC));
+
+        LocalConnector connector = _connectors[0];
+        LocalConnector.LocalEndPoint blocker = connector.executeRequest(newRequest("/block?priority=0"));
+        assertTrue(BlockingServlet.__entered.await(5, TimeUnit.SECONDS));
+
+        List<LocalConnector.LocalEndPoint> endPoints = new ArrayList<>();
+        for (int priority = 0; priority <= 1; ++priority)
+        {
+            for (int i = 0; i < 4; ++i)
+            {
+                endPoints.add(connector.executeRequest(newRequest("/record?priority=" + priority + "&id=" + priority + "." + i)));
+                awaitQueueSize(filter, priority, i + 1);
+            }
+        }
+
+        BlockingServlet.__release.countDown();
+        assertThat(HttpTester.parseResponse(blocker.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        for (LocalConnector.LocalEndPoint endPoint : endPoints)
+        {
+            assertThat(HttpTester.parseResponse(endPoint.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        }
+
+        // Priority 1 is resumed 3 times as often as priority 0, rather than always first.
+        assertThat(RecordingServlet.__requests, contains("1.0", "1.1", "0.0", "1.2", "1.3", "0.1", "0.2", "0.3"));
+    }
+
+    @Test
+    public void testRuleClassifierAndStarvation() throws Exception
+    {
+        QoSFilter filter = new QoSFilter();
+        BlockingQueue<Integer> starved = new LinkedBlockingQueue<>();
+        filter.addStarvationListener((qosFilter, request, priority, waitedMs) -> starved.offer(priority));
+        FilterHolder holder = new FilterHolder(filter);
+        holder.setAsyncSupported(true);
+        holder.setInitParameter(QoSFilter.MAX_REQUESTS_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.MAX_PRIORITY_INIT_PARAM, "2");
+        holder.setInitParameter(QoSFilter.MAX_WAIT_INIT_PARAM, "1");
+        holder.setInitParameter(QoSFilter.PRIORITY_SUSPEND_INIT_PARAM, "-1,200");
+        holder.setInitParameter(QoSFilter.PRIORITY_RULES_INIT_PARAM, "header:X-Tier:gold=2, path:/record=1");
+        context.getServletHandler().addFilterWithMapping(holder, "/*", EnumSet.of(DispatcherType.REQUEST, DispatcherType.ASYNC));
+
+        LocalConnector connector = _connectors[0];
+        LocalConnector.LocalEndPoint blocker = connector.executeRequest(newRequest("/block"));
+        assertTrue(BlockingServlet.__entered.await(5, TimeUnit.SECONDS));
+
+        // Classified by path, suspended at priority 1 until it times out.
+        HttpTester.Response expired = HttpTester.parseResponse(connector.getResponse(newRequest("/record?id=expired")));
+        assertThat(expired.getStatus(), is(HttpStatus.SERVICE_UNAVAILABLE_503));
+        assertThat(starved.poll(5, TimeUnit.SECONDS), is(1));
+        assertThat(filter.getStarvedCount(1), is(1L));
+
+        // Classified by header, suspended at priority 2.
+        String gold = "GET /context/record?id=gold HTTP/1.1\r\nHost: localhost\r\nX-Tier: GOLD\r\nConnection: close\r\n\r\n";
+        LocalConnector.LocalEndPoint queued = connector.executeRequest(gold);
+        awaitQueueSize(filter, 2, 1);
+
+        BlockingServlet.__release.countDown();
+        assertThat(HttpTester.parseResponse(blocker.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(HttpTester.parseResponse(queued.getResponse()).getStatus(), is(HttpStatus.OK_200));
+        assertThat(RecordingServlet.__requests, contains("gold"));
+    }
+
+    private static String newRequest(String uri)
+    {
+        return "GET /context" + uri + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n";
+    }
+
+    private static void awaitQueueSize(QoSFilter filter, int priority, int size) throws InterruptedException
+    {
+        long deadline = System.nanoTime() + TimeUnit.SECONDS.toNanos(5);
+        while (filter.getQueueSize(priority) != size)
+        {
+            assertTrue(System.nanoTime() < deadline, "queue size for priority " + priority);
+            Thread.sleep(10);
+        }
+    }
+
     private void rethrowExceptions(List<Future<Void>> futures) throws Exception
     {
         for (Future<Void> future : futures)
@@ -261,6 +394,38 @@ public class QoSFilterTest
         }
     }
 
+    public static class BlockingServlet extends HttpServlet
+    {
+        private static volatile CountDownLatch __entered;
+        private static volatile CountDownLatch __release;
+
+        @Override
+        protected void doGet(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+        {
+            try
+            {
+                __entered.countDown();
+                if (!__release.await(10, TimeUnit.SECONDS))
+                    response.sendError(500);
+            }
+            catch (InterruptedException e)
+            {
+                response.sendError(500);
+            }
+        }
+    }
+
+    public static class RecordingServlet extends HttpServlet
+    {
+        private static final List<String> __requests = Collections.synchronizedList(new ArrayList<>());
+
+        @Override
+        protected void doGet(HttpServletRequest request, HttpServletResponse response)
+        {
+            __requests.add(request.getParameter("id"));
+        }
+    }
+
     public static class QoSFilter2 extends QoSFilter
     {
         @Override
@@ -15,8 +15,8 @@ package org.eclipse.jetty.logging;
 
 import org.slf4j.ILoggerFactory;
 import org.slf4j.IMarkerFactory;
+import org.slf4j.helpers.BasicMDCAdapter;
 import org.slf4j.helpers.BasicMarkerFactory;
-import org.slf4j.helpers.NOPMDCAdapter;
 import org.slf4j.spi.MDCAdapter;
 import org.slf4j.spi.SLF4JServiceProvider;
 
@@ -37,7 +37,9 @@ public class JettyLoggingServiceProvider implements SLF4JServiceProvider
         JettyLoggerConfiguration config = new JettyLoggerConfiguration().load(this.getClass().getClassLoader());
         loggerFactory = new JettyLoggerFactory(config);
         markerFactory = new BasicMarkerFactory();
-        mdcAdapter = new NOPMDCAdapter(); // TODO: Provide Jetty Implementation?
+        // The MDC is not rendered by JettyLogger, but it is used to propagate
+        // contextual information, such as the request ID, within Jetty.
+        mdcAdapter = new BasicMDCAdapter();
     }
 
     public JettyLoggerFactory getJettyLoggerFactory()
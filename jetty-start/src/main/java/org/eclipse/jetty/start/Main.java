@@ -21,6 +21,7 @@ import java.io.InputStreamReader;
 import java.io.LineNumberReader;
 import java.io.OutputStream;
 import java.io.PrintStream;
+import java.io.PrintWriter;
 import java.lang.reflect.InvocationTargetException;
 import java.lang.reflect.Method;
 import java.net.ConnectException;
@@ -325,6 +326,10 @@ public class Main
         StartLog.debug("Registering all modules");
         modules.registerAll();
 
+        // Environment properties, overriding those of the ini files
+        if (args.isExecEnv())
+            args.parseEnvironment(System.getenv(), modules);
+
         // 4) Active Module Resolution
         List<String> selectedModules = args.getSelectedModules();
         List<String> sortedSelectedModules = modules.getSortedNames(selectedModules);
@@ -436,6 +441,42 @@ public class Main
             writer.write(args.getAllModules(), outputFile);
         }
 
+        // Print the resolved module graph
+        if (args.getModuleGraphFormat() != null)
+        {
+            StartLog.endStartLog();
+            ModuleGraphWriter writer = new ModuleGraphWriter();
+            writer.config(args.getProperties());
+            PrintWriter out = new PrintWriter(System.out);
+            writer.writeResolved(args.getAllModules(), out, args.getModuleGraphFormat());
+        }
+
+        // Explain why modules are enabled
+        if (args.getExplainModules() != null)
+        {
+            StartLog.endStartLog();
+            args.getAllModules().explainModules(System.out, args.getExplainModules());
+        }
+
+        // Generate the systemd service unit
+        if (args.isSystemdUnit())
+        {
+            SystemdUnitWriter writer = new SystemdUnitWriter(baseHome, args);
+            if (args.getSystemdUnitFilename() == null)
+            {
+                StartLog.endStartLog();
+                PrintWriter out = new PrintWriter(System.out);
+                writer.write(out);
+                out.flush();
+            }
+            else
+            {
+                Path outputFile = baseHome.getBasePath(args.getSystemdUnitFilename());
+                System.out.printf("Generating systemd unit for ${jetty.base} at %s%n", baseHome.toShortForm(outputFile));
+                writer.write(outputFile);
+            }
+        }
+
         // Show Command Line to execute Jetty
         if (args.isDryRun())
         {
@@ -476,6 +517,9 @@ public class Main
             return;
         }
 
+        if (args.isValidate())
+            StartLog.info("Validating the configuration of ${jetty.base} = %s", getBaseHome().getBasePath());
+
         // execute Jetty in another JVM
         if (args.isExec())
         {
@@ -505,8 +549,9 @@ public class Main
             copyInThread(process.getErrorStream(), System.err);
             copyInThread(process.getInputStream(), System.out);
             copyInThread(System.in, process.getOutputStream());
-            process.waitFor();
-            System.exit(0); // exit JVM when child process ends.
+            int exitCode = process.waitFor();
+            // exit JVM when child process ends, reporting validation failures.
+            System.exit(args.isValidate() ? exitCode : 0);
             return;
         }
 
@@ -20,10 +20,17 @@ import java.nio.charset.StandardCharsets;
 import java.nio.file.Files;
 import java.nio.file.Path;
 import java.nio.file.StandardOpenOption;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.Comparator;
+import java.util.Iterator;
 import java.util.List;
+import java.util.Set;
+import java.util.TreeSet;
+import java.util.stream.Collectors;
 
 /**
- * Generate a graphviz dot graph of the modules found
+ * Generate a graphviz dot graph, or a JSON document, of the modules found
  */
 public class ModuleGraphWriter
 {
@@ -76,11 +83,25 @@ public class ModuleGraphWriter
         return val;
     }
 
+    /**
+     * Writes the graph of all the modules to the given file, as JSON
+     * if the file name ends with {@code .json}, otherwise as graphviz dot.
+     *
+     * @param modules the modules
+     * @param outputFile the file to write
+     * @throws IOException if the file cannot be written
+     */
     public void write(Modules modules, Path outputFile) throws IOException
     {
         try (BufferedWriter writer = Files.newBufferedWriter(outputFile, StandardCharsets.UTF_8, StandardOpenOption.CREATE_NEW, StandardOpenOption.WRITE);
              PrintWriter out = new PrintWriter(writer))
         {
+            if (outputFile.getFileName().toString().endsWith(".json"))
+            {
+                writeJson(out, modules, false);
+                return;
+            }
+
             writeHeaderMessage(out, outputFile);
 
             out.println();
@@ -116,6 +137,178 @@ public class ModuleGraphWriter
         }
     }
 
+    /**
+     * Writes the resolved module graph, made of the enabled modules only,
+     * where each dependency links to the enabled module that satisfies it.
+     *
+     * @param modules the modules
+     * @param out the writer
+     * @param format either {@code dot} or {@code json}
+     */
+    public void writeResolved(Modules modules, PrintWriter out, String format)
+    {
+        if ("json".equalsIgnoreCase(format))
+        {
+            writeJson(out, modules, true);
+        }
+        else if ("dot".equalsIgnoreCase(format))
+        {
+            out.println("digraph modules {");
+            out.println("  node [color=gray, style=filled, shape=rectangle];");
+            out.println("  node [fontname=\"Verdana\", size=\"20,20\"];");
+            out.println("  graph [fontname=\"Verdana\", rankdir = LR, labeljust = l, label = \"Resolved Jetty Modules\"];");
+            List<Module> enabled = modules.getEnabled();
+            for (Module module : enabled)
+            {
+                writeModuleNode(out, module, true);
+            }
+            for (Module module : enabled)
+            {
+                for (String[] edge : getResolvedDependencies(modules, module))
+                {
+                    if (edge[1].equals(edge[0]))
+                        out.printf("    \"%s\" -> \"%s\";%n", module.getName(), edge[1]);
+                    else
+                        out.printf("    \"%s\" -> \"%s\" [ label=\"%s\" ];%n", module.getName(), edge[1], edge[0]);
+                }
+            }
+            out.println("}");
+        }
+        else
+        {
+            throw new UsageException(UsageException.ERR_BAD_ARG, "Unknown module graph format '%s', expected 'dot' or 'json'", format);
+        }
+        out.flush();
+    }
+
+    /**
+     * @return pairs of (dependency name, enabled provider name)
+     */
+    private List<String[]> getResolvedDependencies(Modules modules, Module module)
+    {
+        List<String[]> edges = new ArrayList<>();
+        for (String depends : module.getDepends())
+        {
+            String name = Module.normalizeModuleName(depends);
+            for (Module provider : modules.getEnabledProviders(name))
+            {
+                if (!provider.equals(module))
+                    edges.add(new String[]{name, provider.getName()});
+            }
+        }
+        return edges;
+    }
+
+    private void writeJson(PrintWriter out, Modules modules, boolean resolved)
+    {
+        List<Module> list = resolved
+            ? modules.getEnabled()
+            : modules.stream().sorted(Comparator.comparing(Module::getName)).collect(Collectors.toList());
+
+        out.println("{");
+        out.printf("  \"resolved\": %b,%n", resolved);
+        out.println("  \"modules\": [");
+        for (Iterator<Module> i = list.iterator(); i.hasNext(); )
+        {
+            Module module = i.next();
+            Set<String> provides = module.getProvides().stream()
+                .map(Modules::toProvidedName)
+                .filter(p -> !p.equals(module.getName()))
+                .collect(Collectors.toCollection(TreeSet::new));
+            out.print("    {");
+            out.printf("\"name\": %s", quote(module.getName()));
+            out.printf(", \"enabled\": %b", module.isEnabled());
+            out.printf(", \"transitive\": %b", module.isTransitive());
+            out.printf(", \"sources\": %s", array(new TreeSet<>(module.getEnableSources())));
+            if (module.isEnabled())
+                out.printf(", \"rootSources\": %s", array(modules.getRootSources(module)));
+            out.printf(", \"provides\": %s", array(provides));
+            out.printf(", \"tags\": %s", array(module.getTags()));
+            out.printf(", \"xmls\": %s", array(module.getXmls()));
+            out.printf(", \"libs\": %s", array(module.getLibs()));
+            out.print("}");
+            out.println(i.hasNext() ? "," : "");
+        }
+        out.println("  ],");
+
+        List<String> edges = new ArrayList<>();
+        for (Module module : list)
+        {
+            if (resolved)
+            {
+                for (String[] edge : getResolvedDependencies(modules, module))
+                {
+                    edges.add(String.format("{\"from\": %s, \"to\": %s, \"type\": \"depends\", \"dependency\": %s}",
+                        quote(module.getName()), quote(edge[1]), quote(edge[0])));
+                }
+            }
+            else
+            {
+                for (String depends : module.getDepends())
+                {
+                    edges.add(String.format("{\"from\": %s, \"to\": %s, \"type\": \"depends\", \"conditional\": %b}",
+                        quote(module.getName()), quote(Module.normalizeModuleName(depends)), Module.isConditionalDependency(depends)));
+                }
+                for (String after : module.getAfter())
+                {
+                    edges.add(String.format("{\"from\": %s, \"to\": %s, \"type\": \"after\"}", quote(module.getName()), quote(after)));
+                }
+                for (String before : module.getBefore())
+                {
+                    edges.add(String.format("{\"from\": %s, \"to\": %s, \"type\": \"before\"}", quote(before), quote(module.getName())));
+                }
+            }
+        }
+        out.println("  \"edges\": [");
+        for (Iterator<String> i = edges.iterator(); i.hasNext(); )
+        {
+            out.print("    ");
+            out.print(i.next());
+            out.println(i.hasNext() ? "," : "");
+        }
+        out.println("  ]");
+        out.println("}");
+    }
+
+    private static String array(Collection<String> values)
+    {
+        return values.stream().map(ModuleGraphWriter::quote).collect(Collectors.joining(", ", "[", "]"));
+    }
+
+    private static String quote(String value)
+    {
+        StringBuilder json = new StringBuilder(value.length() + 2);
+        json.append('"');
+        for (char c : value.toCharArray())
+        {
+            switch (c)
+            {
+                case '"':
+                    json.append("\\\"");
+                    break;
+                case '\\':
+                    json.append("\\\\");
+                    break;
+                case '\n':
+                    json.append("\\n");
+                    break;
+                case '\r':
+                    json.append("\\r");
+                    break;
+                case '\t':
+                    json.append("\\t");
+                    break;
+                default:
+                    if (c < 0x20)
+                        json.append(String.format("\\u%04x", (int)c));
+                    else
+                        json.append(c);
+                    break;
+            }
+        }
+        return json.append('"').toString();
+    }
+
     private void writeHeaderMessage(PrintWriter out, Path outputFile)
     {
         out.println("/*");
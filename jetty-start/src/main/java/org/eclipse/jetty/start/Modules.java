@@ -28,6 +28,7 @@ import java.util.Map;
 import java.util.Optional;
 import java.util.Properties;
 import java.util.Set;
+import java.util.TreeSet;
 import java.util.concurrent.atomic.AtomicBoolean;
 import java.util.concurrent.atomic.AtomicReference;
 import java.util.function.Consumer;
@@ -46,6 +47,7 @@ public class Modules implements Iterable<Module>
     private final Map<String, Module> _names = new HashMap<>();
     private final Map<String, Set<Module>> _provided = new HashMap<>();
     private final Map<String, String> _providedDefaults = new HashMap<>();
+    private final Map<String, Set<String>> _dependents = new HashMap<>();
     private final BaseHome _baseHome;
     private final StartArgs _args;
     private final Properties _deprecated = new Properties();
@@ -434,8 +436,9 @@ public class Modules implements Iterable<Module>
         }
 
         // Check that this is not already provided by another module!
-        for (String name : module.getProvides())
+        for (String provides : module.getProvides())
         {
+            String name = toProvidedName(provides);
             Set<Module> providers = _provided.get(name);
             if (providers != null)
             {
@@ -447,7 +450,7 @@ public class Modules implements Iterable<Module>
                         if (p.isTransitive() && !transitive)
                             p.clearTransitiveEnable();
                         else
-                            throw new UsageException("Module %s provides %s, which is already provided by %s enabled in %s", module.getName(), name, p.getName(), p.getEnableSources());
+                            throw new UsageException("%s", conflict(module, enabledFrom, transitive, name, p));
                     }
                 }
             }
@@ -503,7 +506,7 @@ public class Modules implements Iterable<Module>
                         if (providers == null || providers.isEmpty())
                             throw new UsageException("Module %s does not provide %s", _baseHome.toShortForm(file), dependentModule);
 
-                        enable(newlyEnabled, providers.stream().findFirst().get(), "dynamic dependency of " + module.getName(), true);
+                        enableDependency(newlyEnabled, providers.stream().findFirst().get(), "dynamic dependency of " + module.getName(), module);
                         continue;
                     }
                 }
@@ -519,7 +522,7 @@ public class Modules implements Iterable<Module>
 
             // If a provider is already enabled, then add a transitive enable
             if (providers.stream().anyMatch(Module::isEnabled))
-                providers.stream().filter(m -> m.isEnabled() && !m.equals(module)).forEach(m -> enable(newlyEnabled, m, "transitive provider of " + dependentModule + " for " + module.getName(), true));
+                providers.stream().filter(m -> m.isEnabled() && !m.equals(module)).forEach(m -> enableDependency(newlyEnabled, m, "transitive provider of " + dependentModule + " for " + module.getName(), module));
             else
             {
                 Optional<Module> dftProvider = findDefaultProvider(providers, dependentModule);
@@ -527,12 +530,146 @@ public class Modules implements Iterable<Module>
                 if (dftProvider.isPresent())
                 {
                     StartLog.debug("Using [%s] provider as default for [%s]", dftProvider.get(), dependentModule);
-                    enable(newlyEnabled, dftProvider.get(), "transitive provider of " + dependentModule + " for " + module.getName(), true);
+                    enableDependency(newlyEnabled, dftProvider.get(), "transitive provider of " + dependentModule + " for " + module.getName(), module);
                 }
             }
         }
     }
 
+    private void enableDependency(Set<String> newlyEnabled, Module dependency, String enabledFrom, Module dependent)
+    {
+        _dependents.computeIfAbsent(dependency.getName(), k -> new HashSet<>()).add(dependent.getName());
+        enable(newlyEnabled, dependency, enabledFrom, true);
+    }
+
+    private String conflict(Module module, String enabledFrom, boolean transitive, String capability, Module enabled)
+    {
+        Set<String> moduleSources = transitive ? getRootSources(module) : Set.of(enabledFrom);
+        Set<String> enabledSources = getRootSources(enabled);
+        String providers = _provided.getOrDefault(capability, Set.of()).stream()
+            .map(Module::getName)
+            .sorted()
+            .collect(Collectors.joining(", "));
+        String nl = System.lineSeparator();
+        StringBuilder message = new StringBuilder();
+        message.append(String.format("Module [%s] provides [%s], which is already provided by module [%s]%n", module.getName(), capability, enabled.getName()));
+        message.append(String.format("  [%s] enabled by: %s%n", module.getName(), String.join(", ", moduleSources)));
+        message.append(String.format("  [%s] enabled by: %s%n", enabled.getName(), String.join(", ", enabledSources)));
+        message.append(String.format("  Modules providing [%s]: %s%n", capability, providers));
+        message.append(String.format("Only one module providing [%s] may be enabled: remove the --module of the unwanted module", capability));
+        message.append(" (or of the module that depends on it) from the sources listed above.").append(nl);
+        message.append(String.format("Use --explain-module=%s,%s for details.", module.getName(), enabled.getName()));
+        return message.toString();
+    }
+
+    /**
+     * @param module the module
+     * @return the non transitive sources (ini files or command line) that caused the module to be enabled
+     */
+    public Set<String> getRootSources(Module module)
+    {
+        Set<String> sources = new TreeSet<>();
+        collectRootSources(module, sources, new HashSet<>());
+        return sources;
+    }
+
+    private void collectRootSources(Module module, Set<String> sources, Set<String> visited)
+    {
+        if (!visited.add(module.getName()))
+            return;
+        if (module.isEnabled() && !module.isTransitive())
+        {
+            sources.addAll(module.getEnableSources());
+            return;
+        }
+        for (String dependent : _dependents.getOrDefault(module.getName(), Set.of()))
+        {
+            Module m = _names.get(dependent);
+            if (m != null)
+                collectRootSources(m, sources, visited);
+        }
+    }
+
+    /**
+     * @param name the module name, or a name provided by modules
+     * @return the enabled modules with the given name, or providing the given name
+     */
+    public Set<Module> getEnabledProviders(String name)
+    {
+        return _modules.stream()
+            .filter(Module::isEnabled)
+            .filter(m -> m.getName().equals(name) || m.getProvides().stream().map(Modules::toProvidedName).anyMatch(name::equals))
+            .collect(Collectors.toCollection(() -> new TreeSet<>(Comparator.comparing(Module::getName))));
+    }
+
+    /**
+     * @param name the module name
+     * @return the names of the enabled modules that transitively enabled the given module
+     */
+    public Set<String> getDependents(String name)
+    {
+        return _dependents.getOrDefault(name, Set.of()).stream()
+            .filter(d -> _names.containsKey(d) && _names.get(d).isEnabled())
+            .collect(Collectors.toCollection(TreeSet::new));
+    }
+
+    static String toProvidedName(String provides)
+    {
+        // Strip the "|default" suffix.
+        int idx = provides.indexOf('|');
+        return idx > 0 ? provides.substring(0, idx) : provides;
+    }
+
+    public void explainModules(PrintStream out, List<String> names)
+    {
+        for (String name : names)
+        {
+            out.printf("%nModule: %s%n", name);
+            Module module = get(name);
+            if (module == null)
+            {
+                Set<Module> providers = getEnabledProviders(name);
+                if (providers.isEmpty())
+                    out.printf("  unknown module%n");
+                else
+                    providers.forEach(p -> out.printf("  provided by enabled module %s%n", p.getName()));
+                continue;
+            }
+
+            if (!module.isEnabled())
+            {
+                out.printf("  not enabled%n");
+                Set<Module> providers = getEnabledProviders(name);
+                providers.remove(module);
+                providers.forEach(p -> out.printf("  provided by enabled module %s%n", p.getName()));
+                continue;
+            }
+
+            explainModule(out, module, "  ", new HashSet<>());
+        }
+    }
+
+    private void explainModule(PrintStream out, Module module, String indent, Set<String> visited)
+    {
+        visited.add(module.getName());
+        Set<String> dependents = getDependents(module.getName());
+        // The sources of transitive modules are explained by their dependents.
+        if (!module.isTransitive() || dependents.isEmpty())
+        {
+            new TreeSet<>(module.getEnableSources()).forEach(source -> out.printf("%senabled by %s%n", indent, source));
+        }
+        for (String dependent : dependents)
+        {
+            if (visited.contains(dependent))
+            {
+                out.printf("%srequired by %s (see above)%n", indent, dependent);
+                continue;
+            }
+            out.printf("%srequired by %s%n", indent, dependent);
+            explainModule(out, _names.get(dependent), indent + "  ", visited);
+        }
+    }
+
     private Optional<Module> findDefaultProvider(Set<Module> providers, String dependsOn)
     {
         // Is it obvious?
@@ -36,6 +36,10 @@ import static org.eclipse.jetty.start.UsageException.ERR_BAD_ARG;
  * <p>
  * This is larger in scope than the standard {@link java.util.Properties}, as it will also handle tracking the origin of each property, if it was overridden,
  * and also allowing for <code>${property}</code> expansion.
+ * <p>
+ * Environment variables and system properties can also be referenced as <code>${env.NAME}</code> and
+ * <code>${sys.name}</code>, optionally with a default value as in <code>${env.NAME:-default}</code>.
+ * In {@link #isStrict() strict} mode, a reference to an undefined variable without a default value fails the expansion.
  */
 public final class Props implements Iterable<Prop>
 {
@@ -106,6 +110,8 @@ public final class Props implements Iterable<Prop>
 
     private Map<String, Prop> props = new TreeMap<>(String.CASE_INSENSITIVE_ORDER);
     private List<String> sysPropTracking = new ArrayList<>();
+    private Map<String, String> environment = System.getenv();
+    private boolean strict;
 
     public void addAll(Props other)
     {
@@ -113,6 +119,30 @@ public final class Props implements Iterable<Prop>
         this.sysPropTracking.addAll(other.sysPropTracking);
     }
 
+    /**
+     * @return whether expanding a reference to an undefined environment variable or system property fails
+     */
+    public boolean isStrict()
+    {
+        return strict;
+    }
+
+    /**
+     * @param strict whether expanding a reference to an undefined environment variable or system property fails
+     */
+    public void setStrict(boolean strict)
+    {
+        this.strict = strict;
+    }
+
+    /**
+     * @param environment the environment variables referenced by <code>${env.NAME}</code>, by default {@link System#getenv()}
+     */
+    public void setEnvironment(Map<String, String> environment)
+    {
+        this.environment = environment;
+    }
+
     /**
      * Add a potential argument as a property.
      * <p>
@@ -221,6 +251,8 @@ public final class Props implements Iterable<Prop>
             expanded.append(str.subSequence(offset, mat.start()));
             // get property value
             value = getString(property);
+            if (value == null)
+                value = getVariable(property);
             if (value == null)
             {
                 StartLog.trace("Unable to expand: %s", property);
@@ -250,6 +282,29 @@ public final class Props implements Iterable<Prop>
         return expanded.toString();
     }
 
+    private String getVariable(String reference)
+    {
+        boolean env = reference.startsWith("env.");
+        if (!env && !reference.startsWith("sys."))
+            return null;
+
+        String name = reference.substring(4);
+        String defaultValue = null;
+        int separator = name.indexOf(":-");
+        if (separator >= 0)
+        {
+            defaultValue = name.substring(separator + 2);
+            name = name.substring(0, separator);
+        }
+
+        String value = env ? environment.get(name) : System.getProperty(name);
+        if (value == null)
+            value = defaultValue;
+        if (value == null && strict)
+            throw new PropsException("Undefined variable: " + reference);
+        return value;
+    }
+
     public Prop getProp(String key)
     {
         return getProp(key, true);
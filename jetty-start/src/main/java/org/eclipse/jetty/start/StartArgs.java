@@ -34,12 +34,15 @@ import java.util.Map;
 import java.util.Properties;
 import java.util.Set;
 import java.util.StringTokenizer;
+import java.util.TreeMap;
 import java.util.jar.Attributes;
 import java.util.jar.Manifest;
+import java.util.regex.Matcher;
 import java.util.stream.Collectors;
 import java.util.stream.StreamSupport;
 
 import org.eclipse.jetty.start.Props.Prop;
+import org.eclipse.jetty.start.config.CommandLineConfigSource;
 import org.eclipse.jetty.start.config.ConfigSource;
 import org.eclipse.jetty.start.config.ConfigSources;
 import org.eclipse.jetty.start.config.DirConfigSource;
@@ -55,6 +58,7 @@ public class StartArgs
     public static final Set<String> ALL_PARTS = Set.of("java", "opts", "path", "main", "args");
     public static final Set<String> ARG_PARTS = Set.of("args");
     public static final String ARG_ALLOW_INSECURE_HTTP_DOWNLOADS = "--allow-insecure-http-downloads";
+    public static final String ENV_PROPERTY_PREFIX = "JETTY_PROP_";
 
     private static final String JETTY_VERSION_KEY = "jetty.version";
     private static final String JETTY_TAG_NAME_KEY = "jetty.tag.version";
@@ -183,6 +187,22 @@ public class StartArgs
      */
     private String moduleGraphFilename;
 
+    /**
+     * --list-module-graph[=dot|json]
+     */
+    private String moduleGraphFormat;
+
+    /**
+     * --explain-modules=[module,[module]]
+     */
+    private List<String> explainModules = null;
+
+    /**
+     * --systemd-unit[=filename]
+     */
+    private boolean systemdUnit = false;
+    private String systemdUnitFilename;
+
     /**
      * Collection of all modules
      */
@@ -208,6 +228,7 @@ public class StartArgs
     private boolean listConfig = false;
     private boolean version = false;
     private boolean dryRun = false;
+    private boolean validate = false;
     private boolean multiLine = false;
     private final Set<String> dryRunParts = new HashSet<>();
     private boolean jpms = false;
@@ -218,6 +239,7 @@ public class StartArgs
 
     private boolean exec = false;
     private String execProperties;
+    private boolean execEnv = false;
     private boolean allowInsecureHttpDownloads = false;
     private boolean approveAllLicenses = false;
 
@@ -919,6 +941,26 @@ public class StartArgs
         return moduleGraphFilename;
     }
 
+    public String getModuleGraphFormat()
+    {
+        return moduleGraphFormat;
+    }
+
+    public List<String> getExplainModules()
+    {
+        return explainModules;
+    }
+
+    public boolean isSystemdUnit()
+    {
+        return systemdUnit;
+    }
+
+    public String getSystemdUnitFilename()
+    {
+        return systemdUnitFilename;
+    }
+
     public Props getProperties()
     {
         return properties;
@@ -994,11 +1036,21 @@ public class StartArgs
         return dryRunParts;
     }
 
+    public boolean isValidate()
+    {
+        return validate;
+    }
+
     public boolean isExec()
     {
         return exec;
     }
 
+    public boolean isExecEnv()
+    {
+        return execEnv;
+    }
+
     public boolean isLicenseCheckRequired()
     {
         return licenseCheckRequired;
@@ -1243,6 +1295,14 @@ public class StartArgs
             return;
         }
 
+        // Validate the configuration without serving traffic
+        if ("--validate".equals(arg))
+        {
+            validate = true;
+            properties.setProperty("jetty.validate", "true", source);
+            return;
+        }
+
         // Enable forked execution of Jetty server
         if ("--exec".equals(arg))
         {
@@ -1259,6 +1319,13 @@ public class StartArgs
             return;
         }
 
+        // Take the properties from JETTY_PROP_* environment variables
+        if ("--exec-env".equals(arg))
+        {
+            execEnv = true;
+            return;
+        }
+
         // Allow insecure-http downloads
         if (ARG_ALLOW_INSECURE_HTTP_DOWNLOADS.equals(arg))
         {
@@ -1273,6 +1340,13 @@ public class StartArgs
             return;
         }
 
+        // Fail the expansion of undefined environment variables and system properties
+        if ("--strict-variables".equals(arg))
+        {
+            properties.setStrict(true);
+            return;
+        }
+
         // Arbitrary Libraries
         if (arg.startsWith("--lib="))
         {
@@ -1383,6 +1457,48 @@ public class StartArgs
             return;
         }
 
+        // Print the resolved module graph
+        if ("--list-module-graph".equals(arg))
+        {
+            this.moduleGraphFormat = "dot";
+            run = false;
+            return;
+        }
+
+        if (arg.startsWith("--list-module-graph="))
+        {
+            String format = Props.getValue(arg);
+            if (!"dot".equalsIgnoreCase(format) && !"json".equalsIgnoreCase(format))
+                throw new UsageException(UsageException.ERR_BAD_ARG, "Unrecognized --list-module-graph=\"%s\" in %s, expected dot or json", format, source);
+            this.moduleGraphFormat = format;
+            run = false;
+            return;
+        }
+
+        // Generate a systemd service unit
+        if ("--systemd-unit".equals(arg))
+        {
+            systemdUnit = true;
+            run = false;
+            return;
+        }
+
+        if (arg.startsWith("--systemd-unit="))
+        {
+            systemdUnit = true;
+            systemdUnitFilename = Props.getValue(arg);
+            run = false;
+            return;
+        }
+
+        // Explain why modules are enabled
+        if (arg.startsWith("--explain-module=") || arg.startsWith("--explain-modules="))
+        {
+            explainModules = Props.getValues(arg);
+            run = false;
+            return;
+        }
+
         // Start property (syntax similar to System property)
         if (arg.startsWith("-D"))
         {
@@ -1508,6 +1624,77 @@ public class StartArgs
         setProperty(key, value, source);
     }
 
+    /**
+     * <p>Sets the properties specified by <code>JETTY_PROP_&lt;name&gt;</code> environment variables.</p>
+     * <p>In the name, a single <code>_</code> maps to <code>.</code> and a double <code>__</code> maps
+     * to <code>_</code>, so that <code>JETTY_PROP_jetty_http_port</code> sets <code>jetty.http.port</code>.
+     * A name that matches, ignoring case, a property already set or a property of the
+     * <code>[ini-template]</code> section of a module maps to that property, so that
+     * <code>JETTY_PROP_JETTY_HTTP_IDLETIMEOUT</code> sets <code>jetty.http.idleTimeout</code>.</p>
+     * <p>The environment properties override the properties of the ini files,
+     * but not the properties specified on the command line.</p>
+     *
+     * @param environment the environment variables
+     * @param modules the modules whose <code>[ini-template]</code> properties are known
+     */
+    public void parseEnvironment(Map<String, String> environment, Modules modules)
+    {
+        Map<String, String> knownNames = new HashMap<>();
+        for (Module module : modules)
+        {
+            for (String line : module.getIniTemplate())
+            {
+                Matcher matcher = Module.SET_PROPERTY.matcher(line);
+                if (matcher.matches() && !matcher.group(2).startsWith("-"))
+                {
+                    String name = matcher.group(2).replaceAll("[+?]$", "");
+                    knownNames.putIfAbsent(name.toLowerCase(), name);
+                }
+            }
+        }
+        for (Prop prop : properties)
+        {
+            knownNames.put(prop.key.toLowerCase(), prop.key);
+        }
+
+        for (Map.Entry<String, String> entry : new TreeMap<>(environment).entrySet())
+        {
+            String variable = entry.getKey();
+            if (!variable.startsWith(ENV_PROPERTY_PREFIX) || variable.length() == ENV_PROPERTY_PREFIX.length())
+                continue;
+
+            String key = toPropertyName(variable.substring(ENV_PROPERTY_PREFIX.length()));
+            if (!knownNames.containsValue(key))
+                key = knownNames.getOrDefault(key.toLowerCase(), key);
+
+            Prop prop = properties.getProp(key);
+            if (prop != null && prop.source != null && prop.source.contains(CommandLineConfigSource.ORIGIN_CMD_LINE))
+            {
+                StartLog.debug("Ignoring %s, property %s is set on the command line", variable, key);
+                continue;
+            }
+
+            StartLog.debug("Environment %s sets property %s", variable, key);
+            setProperty(key, entry.getValue(), "<env:" + variable + ">");
+        }
+    }
+
+    private static String toPropertyName(String name)
+    {
+        StringBuilder builder = new StringBuilder(name.length());
+        for (int i = 0; i < name.length(); i++)
+        {
+            char c = name.charAt(i);
+            if (c != '_')
+                builder.append(c);
+            else if (i + 1 < name.length() && name.charAt(i + 1) == '_')
+                builder.append(name.charAt(++i));
+            else
+                builder.append('.');
+        }
+        return builder.toString();
+    }
+
     private void selectModules(String source, List<String> moduleNames)
     {
         for (String moduleName : moduleNames)
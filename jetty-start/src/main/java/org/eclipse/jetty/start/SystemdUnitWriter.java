@@ -0,0 +1,173 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.start;
+
+import java.io.BufferedWriter;
+import java.io.IOException;
+import java.io.PrintWriter;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.stream.Collectors;
+
+import org.eclipse.jetty.start.Props.Prop;
+
+/**
+ * <p>Generates a hardened systemd service unit that runs Jetty for the current <code>${jetty.base}</code>.</p>
+ * <p>The service runs <code>start.jar</code> as an unprivileged user, with a read-only file system
+ * except for <code>${jetty.base}</code>, so that changes to the ini files apply when the service restarts.
+ * The unit can be customized with the properties:</p>
+ * <ul>
+ * <li><code>jetty.systemd.user</code> - the user that runs the service, by default <code>jetty</code></li>
+ * <li><code>jetty.systemd.group</code> - the group that runs the service, by default the user</li>
+ * <li><code>jetty.systemd.description</code> - the description of the service</li>
+ * <li><code>jetty.systemd.environmentFile</code> - an optional file of environment variables,
+ * by default <code>/etc/default/jetty</code> when <code>--exec-env</code> is used</li>
+ * </ul>
+ */
+public class SystemdUnitWriter
+{
+    private final BaseHome baseHome;
+    private final StartArgs args;
+
+    public SystemdUnitWriter(BaseHome baseHome, StartArgs args)
+    {
+        this.baseHome = baseHome;
+        this.args = args;
+    }
+
+    public void write(Path outputFile) throws IOException
+    {
+        try (BufferedWriter writer = Files.newBufferedWriter(outputFile, StandardCharsets.UTF_8);
+             PrintWriter out = new PrintWriter(writer))
+        {
+            write(out);
+        }
+    }
+
+    public void write(PrintWriter out)
+    {
+        Props props = args.getProperties();
+        String base = baseHome.getBasePath().toString();
+        String user = props.getString("jetty.systemd.user", "jetty");
+        String group = props.getString("jetty.systemd.group", user);
+        String description = props.getString("jetty.systemd.description", "Jetty " + StartArgs.VERSION + " (" + base + ")");
+        String environmentFile = props.getString("jetty.systemd.environmentFile", args.isExecEnv() ? "/etc/default/jetty" : null);
+
+        List<String> command = new ArrayList<>();
+        command.add(CommandLineBuilder.findJavaBin());
+        command.add("-Djetty.home=" + baseHome.getHome());
+        command.add("-Djetty.base=" + base);
+        command.add("-jar");
+        command.add(baseHome.getHomePath().resolve("start.jar").toString());
+        if (args.isExecEnv())
+            command.add("--exec-env");
+
+        out.println("# Generated by Jetty start.jar --systemd-unit");
+        out.println("[Unit]");
+        out.printf("Description=%s%n", escape(description));
+        out.println("Wants=network-online.target");
+        out.println("After=network-online.target");
+        out.println();
+        out.println("[Service]");
+        out.println("Type=simple");
+        out.printf("User=%s%n", user);
+        out.printf("Group=%s%n", group);
+        out.printf("WorkingDirectory=%s%n", escape(base));
+        if (environmentFile != null)
+            out.printf("EnvironmentFile=-%s%n", escape(environmentFile));
+        out.printf("ExecStart=%s%n", command.stream().map(SystemdUnitWriter::quote).collect(Collectors.joining(" ")));
+        // The JVM exits with 128+SIGTERM when it is stopped.
+        out.println("SuccessExitStatus=143");
+        out.println("Restart=on-failure");
+        out.println("RestartSec=5");
+        out.println("LimitNOFILE=65536");
+        out.println("UMask=0027");
+        out.println();
+        out.println("NoNewPrivileges=true");
+        out.println("PrivateTmp=true");
+        out.println("PrivateDevices=true");
+        out.println("ProtectSystem=strict");
+        out.printf("ProtectHome=%s%n", isUnderHome(base) || isUnderHome(baseHome.getHome()) ? "read-only" : "true");
+        out.printf("ReadWritePaths=%s%n", escape(base));
+        out.println("ProtectKernelTunables=true");
+        out.println("ProtectKernelModules=true");
+        out.println("ProtectControlGroups=true");
+        out.println("RestrictNamespaces=true");
+        out.println("RestrictRealtime=true");
+        out.println("RestrictSUIDSGID=true");
+        out.println("LockPersonality=true");
+        out.println("SystemCallArchitectures=native");
+        if (hasPrivilegedPort(props))
+        {
+            out.println("CapabilityBoundingSet=CAP_NET_BIND_SERVICE");
+            out.println("AmbientCapabilities=CAP_NET_BIND_SERVICE");
+        }
+        else
+        {
+            out.println("CapabilityBoundingSet=");
+        }
+        out.println();
+        out.println("[Install]");
+        out.println("WantedBy=multi-user.target");
+    }
+
+    private boolean hasPrivilegedPort(Props props)
+    {
+        for (Prop prop : props)
+        {
+            if (!prop.key.endsWith(".port") || prop.key.toLowerCase().startsWith("stop."))
+                continue;
+            try
+            {
+                int port = Integer.parseInt(props.expand(prop.value).trim());
+                if (port > 0 && port < 1024)
+                    return true;
+            }
+            catch (NumberFormatException x)
+            {
+                StartLog.debug("Ignoring non numeric port %s=%s", prop.key, prop.value);
+            }
+        }
+        return false;
+    }
+
+    private static boolean isUnderHome(String path)
+    {
+        return path.startsWith("/home/") || path.startsWith("/root/") || path.startsWith("/run/user/");
+    }
+
+    /**
+     * @param value the value of a systemd setting
+     * @return the value with the systemd specifiers escaped
+     */
+    static String escape(String value)
+    {
+        return value.replace("%", "%%");
+    }
+
+    /**
+     * @param arg an argument of the systemd ExecStart command line
+     * @return the argument with the systemd specifiers and variables escaped, quoted if necessary
+     */
+    static String quote(String arg)
+    {
+        String escaped = escape(arg).replace("$", "$$");
+        if (escaped.isEmpty() || escaped.chars().anyMatch(c -> Character.isWhitespace(c) || c == '"' || c == '\'' || c == '\\' || c == ';'))
+            return "\"" + escaped.replace("\\", "\\\\").replace("\"", "\\\"") + "\"";
+        return escaped;
+    }
+}
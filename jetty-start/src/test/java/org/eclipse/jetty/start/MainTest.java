@@ -16,12 +16,15 @@ package org.eclipse.jetty.start;
 import java.io.ByteArrayOutputStream;
 import java.io.File;
 import java.io.PrintStream;
+import java.io.PrintWriter;
+import java.io.StringWriter;
 import java.nio.charset.StandardCharsets;
 import java.nio.file.Files;
 import java.nio.file.Path;
 import java.util.ArrayList;
 import java.util.Iterator;
 import java.util.List;
+import java.util.Map;
 import java.util.stream.Collectors;
 
 import org.eclipse.jetty.toolchain.test.MavenTestingUtils;
@@ -32,6 +35,7 @@ import org.junit.jupiter.api.Test;
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
 import static org.hamcrest.Matchers.not;
 import static org.junit.jupiter.api.Assertions.assertEquals;
 
@@ -180,4 +184,95 @@ public class MainTest
         );
         assertThat(commandLine, containsString(expectedExpansion));
     }
+
+    @Test
+    public void testValidate() throws Exception
+    {
+        List<String> cmdLineArgs = new ArrayList<>();
+
+        Path homePath = MavenTestingUtils.getTestResourceDir("dist-home").toPath().toRealPath();
+        cmdLineArgs.add("jetty.home=" + homePath);
+        cmdLineArgs.add("user.dir=" + homePath);
+        cmdLineArgs.add("--validate");
+        cmdLineArgs.add("--dry-run");
+
+        Main main = new Main();
+        StartArgs args = main.processCommandLine(cmdLineArgs.toArray(new String[0]));
+
+        assertThat("--validate", args.isValidate(), is(true));
+        assertEquals("true", args.getProperties().getString("jetty.validate"), "--validate missing property");
+        // The property is passed to the main class, so that it validates rather than starts the server.
+        String commandLine = args.getMainArgs(StartArgs.ALL_PARTS).toString();
+        assertThat(commandLine, containsString("jetty.validate=true"));
+    }
+
+    @Test
+    public void testExecEnv() throws Exception
+    {
+        List<String> cmdLineArgs = new ArrayList<>();
+
+        Path homePath = MavenTestingUtils.getTestResourceDir("dist-home").toPath().toRealPath();
+        cmdLineArgs.add("jetty.home=" + homePath);
+        cmdLineArgs.add("user.dir=" + homePath);
+        cmdLineArgs.add("--exec-env");
+        cmdLineArgs.add("extra.prop=cmd");
+
+        Main main = new Main();
+        StartArgs args = main.processCommandLine(cmdLineArgs.toArray(new String[0]));
+        assertThat("--exec-env", args.isExecEnv(), is(true));
+
+        args.parseEnvironment(Map.of(
+            "JETTY_PROP_MAIN_PROP", "env",
+            "JETTY_PROP_extra_prop", "env",
+            "JETTY_PROP_custom_some__name", "value",
+            "JETTY_PROP_", "empty",
+            "PATH", "/bin"), args.getAllModules());
+
+        // The case insensitive name maps to the [ini-template] property.
+        assertEquals("env", args.getProperties().getString("main.prop"));
+        assertEquals("<env:JETTY_PROP_MAIN_PROP>", args.getProperties().getProp("main.prop").source);
+        assertThat(args.getProperties().getProp("MAIN.PROP"), nullValue());
+        // The command line wins over the environment.
+        assertEquals("cmd", args.getProperties().getString("extra.prop"));
+        assertEquals("value", args.getProperties().getString("custom.some_name"));
+        assertThat(args.getProperties().getProp("PATH"), nullValue());
+    }
+
+    @Test
+    public void testSystemdUnit() throws Exception
+    {
+        List<String> cmdLineArgs = new ArrayList<>();
+
+        Path homePath = MavenTestingUtils.getTestResourceDir("dist-home").toPath().toRealPath();
+        cmdLineArgs.add("jetty.home=" + homePath);
+        cmdLineArgs.add("user.dir=" + homePath);
+        cmdLineArgs.add("--systemd-unit");
+        cmdLineArgs.add("jetty.systemd.user=www");
+        cmdLineArgs.add("jetty.http.port=80");
+
+        Main main = new Main();
+        StartArgs args = main.processCommandLine(cmdLineArgs.toArray(new String[0]));
+        assertThat("--systemd-unit", args.isSystemdUnit(), is(true));
+        assertThat("--systemd-unit", args.isRun(), is(false));
+
+        StringWriter writer = new StringWriter();
+        try (PrintWriter out = new PrintWriter(writer))
+        {
+            new SystemdUnitWriter(main.getBaseHome(), args).write(out);
+        }
+        String unit = writer.toString();
+
+        assertThat(unit, containsString("[Service]"));
+        assertThat(unit, containsString("User=www"));
+        assertThat(unit, containsString("Group=www"));
+        assertThat(unit, containsString("ProtectSystem=strict"));
+        assertThat(unit, containsString("ReadWritePaths=" + homePath));
+        assertThat(unit, containsString("-jar " + SystemdUnitWriter.quote(homePath.resolve("start.jar").toString())));
+        // Port 80 requires the capability to bind privileged ports.
+        assertThat(unit, containsString("AmbientCapabilities=CAP_NET_BIND_SERVICE"));
+        assertThat(unit, not(containsString("EnvironmentFile")));
+
+        assertEquals("\"/opt/my jetty/100%%$$\"", SystemdUnitWriter.quote("/opt/my jetty/100%$"));
+        assertEquals("/opt/jetty", SystemdUnitWriter.quote("/opt/jetty"));
+    }
 }
@@ -16,6 +16,8 @@ package org.eclipse.jetty.start;
 import java.io.BufferedReader;
 import java.io.IOException;
 import java.io.InputStreamReader;
+import java.io.PrintWriter;
+import java.io.StringWriter;
 import java.nio.charset.StandardCharsets;
 import java.nio.file.Path;
 import java.time.Duration;
@@ -31,7 +33,10 @@ import org.junit.jupiter.api.Test;
 import org.junit.jupiter.api.extension.ExtendWith;
 
 import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.assertTimeout;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
@@ -83,6 +88,42 @@ public class ModuleGraphWriterTest
         });
     }
 
+    @Test
+    public void testWriteResolved() throws IOException
+    {
+        Path homeDir = MavenTestingUtils.getTestResourcePathDir("provider-conflict");
+        Path baseDir = testdir.getEmptyPathDir();
+
+        ConfigSources config = new ConfigSources();
+        config.add(new CommandLineConfigSource(new String[0]));
+        config.add(new JettyHomeConfigSource(homeDir));
+        config.add(new JettyBaseConfigSource(baseDir));
+
+        BaseHome basehome = new BaseHome(config);
+        StartArgs args = new StartArgs(basehome);
+        args.parse(config);
+
+        Modules modules = new Modules(basehome, args);
+        modules.registerAll();
+        modules.enable("http", "start.d/http.ini");
+
+        ModuleGraphWriter writer = new ModuleGraphWriter();
+
+        StringWriter json = new StringWriter();
+        writer.writeResolved(modules, new PrintWriter(json), "json");
+        assertThat(json.toString(), containsString("\"resolved\": true"));
+        assertThat(json.toString(), containsString("{\"from\": \"http\", \"to\": \"logging-a\", \"type\": \"depends\", \"dependency\": \"logging\"}"));
+        assertThat(json.toString(), containsString("\"rootSources\": [\"start.d/http.ini\"]"));
+        assertThat(json.toString(), not(containsString("logging-b")));
+
+        StringWriter dot = new StringWriter();
+        writer.writeResolved(modules, new PrintWriter(dot), "dot");
+        assertThat(dot.toString(), containsString("\"http\" -> \"logging-a\" [ label=\"logging\" ];"));
+        assertThat(dot.toString(), containsString("\"http\" -> \"server\";"));
+
+        assertThrows(UsageException.class, () -> writer.writeResolved(modules, new PrintWriter(new StringWriter()), "xml"));
+    }
+
     private boolean execDotCmd(String... args)
     {
         try
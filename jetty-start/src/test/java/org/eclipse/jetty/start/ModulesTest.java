@@ -13,8 +13,11 @@
 
 package org.eclipse.jetty.start;
 
+import java.io.ByteArrayOutputStream;
 import java.io.File;
 import java.io.IOException;
+import java.io.PrintStream;
+import java.nio.charset.StandardCharsets;
 import java.util.ArrayList;
 import java.util.List;
 
@@ -31,9 +34,11 @@ import org.junit.jupiter.api.extension.ExtendWith;
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.contains;
 import static org.hamcrest.Matchers.containsInAnyOrder;
+import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.greaterThanOrEqualTo;
 import static org.hamcrest.Matchers.is;
 import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
 
 @ExtendWith(WorkDirExtension.class)
 public class ModulesTest
@@ -365,6 +370,63 @@ public class ModulesTest
         return libs;
     }
 
+    @Test
+    public void testExplainTransitiveModule() throws IOException
+    {
+        Modules modules = newProviderConflictModules();
+
+        modules.enable("http", "start.d/http.ini");
+
+        assertThat(modules.get("logging-a").isTransitive(), is(true));
+        assertThat(modules.getDependents("logging-a"), contains("http"));
+        assertThat(modules.getRootSources(modules.get("logging-a")), contains("start.d/http.ini"));
+        assertThat(modules.getEnabledProviders("logging"), contains(modules.get("logging-a")));
+
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        modules.explainModules(new PrintStream(output, true, StandardCharsets.UTF_8), List.of("logging-a", "logging-b"));
+        String explanation = output.toString(StandardCharsets.UTF_8);
+
+        assertThat(explanation, containsString("Module: logging-a"));
+        assertThat(explanation, containsString("  required by http"));
+        assertThat(explanation, containsString("    enabled by start.d/http.ini"));
+        assertThat(explanation, containsString("Module: logging-b"));
+        assertThat(explanation, containsString("  not enabled"));
+    }
+
+    @Test
+    public void testProviderConflict() throws IOException
+    {
+        Modules modules = newProviderConflictModules();
+
+        modules.enable("logging-a", "start.d/logging-a.ini");
+        UsageException failure = assertThrows(UsageException.class, () -> modules.enable("logging-b", "start.d/logging-b.ini"));
+
+        String message = failure.getMessage();
+        assertThat(message, containsString("Module [logging-b] provides [logging], which is already provided by module [logging-a]"));
+        assertThat(message, containsString("[logging-b] enabled by: start.d/logging-b.ini"));
+        assertThat(message, containsString("[logging-a] enabled by: start.d/logging-a.ini"));
+        assertThat(message, containsString("Modules providing [logging]: logging-a, logging-b"));
+    }
+
+    private Modules newProviderConflictModules() throws IOException
+    {
+        File homeDir = MavenTestingUtils.getTestResourceDir("provider-conflict");
+        File baseDir = testdir.getEmptyPathDir().toFile();
+
+        ConfigSources config = new ConfigSources();
+        config.add(new CommandLineConfigSource(new String[0]));
+        config.add(new JettyHomeConfigSource(homeDir.toPath()));
+        config.add(new JettyBaseConfigSource(baseDir.toPath()));
+
+        BaseHome basehome = new BaseHome(config);
+        StartArgs args = new StartArgs(basehome);
+        args.parse(config);
+
+        Modules modules = new Modules(basehome, args);
+        modules.registerAll();
+        return modules;
+    }
+
     private List<String> normalizeXmls(List<Module> active)
     {
         List<String> xmls = new ArrayList<>();
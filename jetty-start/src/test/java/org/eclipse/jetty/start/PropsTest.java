@@ -13,12 +13,15 @@
 
 package org.eclipse.jetty.start;
 
+import java.util.Map;
+
 import org.eclipse.jetty.start.Props.Prop;
 import org.junit.jupiter.api.Test;
 
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.is;
 import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.fail;
 
 public class PropsTest
@@ -125,4 +128,32 @@ public class PropsTest
             assertThat(e.getMessage(), is("Property expansion loop detected: aa -> bb -> cc -> aa"));
         }
     }
+
+    @Test
+    public void testExpandVariables()
+    {
+        Props props = new Props();
+        props.setEnvironment(Map.of("HTTP_PORT", "9090"));
+        props.setProperty("host", "example.com", FROM_TEST);
+
+        assertThat(props.expand("port=${env.HTTP_PORT}"), is("port=9090"));
+        assertThat(props.expand("port=${env.HTTPS_PORT:-8443}"), is("port=8443"));
+        assertThat(props.expand("tmp=${sys.java.io.tmpdir}"), is("tmp=" + System.getProperty("java.io.tmpdir")));
+        assertThat(props.expand("url=${host}:${env.MISSING}"), is("url=example.com:${env.MISSING}"));
+        // Properties take precedence over variables with the same reference.
+        props.setProperty("env.HTTP_PORT", "7070", FROM_TEST);
+        assertThat(props.expand("port=${env.HTTP_PORT}"), is("port=7070"));
+    }
+
+    @Test
+    public void testExpandVariablesStrict()
+    {
+        Props props = new Props();
+        props.setEnvironment(Map.of());
+        props.setStrict(true);
+
+        assertThat(props.expand("port=${env.HTTP_PORT:-8080} ${unknown}"), is("port=8080 ${unknown}"));
+        PropsException x = assertThrows(PropsException.class, () -> props.expand("port=${env.HTTP_PORT}"));
+        assertThat(x.getMessage(), is("Undefined variable: env.HTTP_PORT"));
+    }
 }
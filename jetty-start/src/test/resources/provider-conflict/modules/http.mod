@@ -0,0 +1,6 @@
+[description]
+Test http module
+
+[depend]
+server
+logging
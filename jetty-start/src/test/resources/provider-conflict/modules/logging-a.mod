@@ -0,0 +1,5 @@
+[description]
+Test default logging provider
+
+[provides]
+logging|default
@@ -0,0 +1,5 @@
+[description]
+Test alternative logging provider
+
+[provides]
+logging
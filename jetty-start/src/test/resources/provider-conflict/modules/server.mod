@@ -0,0 +1,2 @@
+[description]
+Test server module
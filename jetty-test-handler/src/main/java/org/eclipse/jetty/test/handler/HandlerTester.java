@@ -0,0 +1,176 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.time.Duration;
+import java.util.Objects;
+import java.util.stream.Stream;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.stub.StubConnector;
+import org.eclipse.jetty.client.stub.StubEndPoint;
+import org.eclipse.jetty.client.stub.StubHttpClientTransport;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.server.Handler;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+
+/**
+ * <p>Drives a server {@link Handler} through the real HTTP codecs over in-memory
+ * connections, so that protocol sensitive handler tests need no sockets.</p>
+ * <p>Requests are built fluently with {@link #request(HttpVersion, String, String)}
+ * and sent by an {@link HttpClient} over HTTP/1.1 or HTTP/2, so that the same test
+ * can be run for each of the {@link #versions() supported versions}:</p>
+ * <pre>
+ * HandlerTester tester = new HandlerTester(handler);
+ * tester.start();
+ * TestResponse response = tester.request(HttpVersion.HTTP_2, "POST", "/upload")
+ *     .content("text/plain", "hello")
+ *     .trailer("Checksum", "abc")
+ *     .expect100Continue()
+ *     .send();
+ * </pre>
+ * <p>Requests that a client would never send, such as malformed requests, are written
+ * byte by byte to a {@link RawConnection} returned by {@link #connect()}, or frame by
+ * frame to a {@link RawHTTP2Connection} returned by {@link #connectHTTP2()}.</p>
+ * <p>HTTP/3 is not supported, because QUIC requires a datagram socket.</p>
+ */
+public class HandlerTester extends ContainerLifeCycle
+{
+    private final Server server;
+    private final StubConnector connector;
+    private final HttpClient client;
+    private Duration timeout = Duration.ofSeconds(5);
+
+    public HandlerTester(Handler handler)
+    {
+        this(handler, new HttpConfiguration());
+    }
+
+    public HandlerTester(Handler handler, HttpConfiguration httpConfiguration)
+    {
+        server = new Server();
+        connector = new StubConnector(server, httpConfiguration);
+        server.addConnector(connector);
+        server.setHandler(Objects.requireNonNull(handler));
+        addBean(server);
+        client = new HttpClient(new StubHttpClientTransport(connector));
+        addBean(client);
+    }
+
+    /**
+     * <p>Returns the HTTP versions supported by this tester, suitable
+     * for a JUnit {@code @MethodSource} that runs a test for each protocol.</p>
+     *
+     * @return the supported HTTP versions
+     */
+    public static Stream<HttpVersion> versions()
+    {
+        return Stream.of(HttpVersion.HTTP_1_1, HttpVersion.HTTP_2);
+    }
+
+    /**
+     * @return the server that hosts the handler
+     */
+    public Server getServer()
+    {
+        return server;
+    }
+
+    /**
+     * @return the server connector, that can be used to inject network faults
+     */
+    public StubConnector getConnector()
+    {
+        return connector;
+    }
+
+    /**
+     * @return the client that sends the requests built by {@link #request(HttpVersion, String, String)}
+     */
+    public HttpClient getHttpClient()
+    {
+        return client;
+    }
+
+    /**
+     * @return the max time to wait for a response or for the bytes read by a raw connection
+     */
+    public Duration getTimeout()
+    {
+        return timeout;
+    }
+
+    /**
+     * @param timeout the max time to wait for a response or for the bytes read by a raw connection
+     */
+    public void setTimeout(Duration timeout)
+    {
+        this.timeout = Objects.requireNonNull(timeout);
+    }
+
+    /**
+     * <p>Creates a request to the handler.</p>
+     *
+     * @param version the HTTP version of the request, one of {@link #versions()} or {@code HTTP/1.0}
+     * @param method the request method
+     * @param target the request target, such as {@code /path?query}
+     * @return a request that is sent by {@link TestRequest#send()}
+     */
+    public TestRequest request(HttpVersion version, String method, String target)
+    {
+        switch (version)
+        {
+            case HTTP_1_0:
+            case HTTP_1_1:
+            case HTTP_2:
+                return new TestRequest(this, version, method, target);
+            default:
+                throw new IllegalArgumentException("Unsupported version " + version);
+        }
+    }
+
+    /**
+     * <p>Opens a connection on which the test writes raw HTTP/1 bytes.</p>
+     *
+     * @return a new raw connection
+     */
+    public RawConnection connect()
+    {
+        return new RawConnection(newEndPoint(), timeout);
+    }
+
+    /**
+     * <p>Opens a connection on which the test writes HTTP/2 frames with prior knowledge;
+     * the connection preface is not sent until {@link RawHTTP2Connection#sendPreface()}.</p>
+     *
+     * @return a new raw HTTP/2 connection
+     */
+    public RawHTTP2Connection connectHTTP2()
+    {
+        return new RawHTTP2Connection(newEndPoint(), timeout, connector.getByteBufferPool());
+    }
+
+    private StubEndPoint newEndPoint()
+    {
+        StubEndPoint endPoint = connector.connect(new InetSocketAddress(InetAddress.getLoopbackAddress(), 80));
+        // The test decides how long to wait for each read.
+        endPoint.setIdleTimeout(0);
+        endPoint.onOpen();
+        return endPoint;
+    }
+}
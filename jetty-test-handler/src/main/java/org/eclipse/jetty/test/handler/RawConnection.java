@@ -0,0 +1,229 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.io.Closeable;
+import java.io.IOException;
+import java.io.InterruptedIOException;
+import java.net.SocketTimeoutException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.time.Duration;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+
+import org.eclipse.jetty.client.stub.StubEndPoint;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.FutureCallback;
+
+/**
+ * <p>A client connection to a {@link HandlerTester} on which the test writes raw bytes,
+ * for example malformed or pipelined HTTP/1 requests, and reads back the responses.</p>
+ * <pre>
+ * try (RawConnection connection = tester.connect())
+ * {
+ *     connection.send("GET / HTTP/1.1\r\nHost: localhost\r\nExpect: 102-processing\r\n\r\n");
+ *     HttpTester.Response processing = connection.readResponse();
+ *     HttpTester.Response response = connection.readResponse();
+ * }
+ * </pre>
+ * <p>Reads block until bytes are available, failing with {@link SocketTimeoutException}
+ * if none arrive within the tester timeout.</p>
+ */
+public class RawConnection implements Closeable
+{
+    private final HttpTester.Input input = new HttpTester.Input()
+    {
+        @Override
+        public int fillBuffer() throws IOException
+        {
+            BufferUtil.compact(_buffer);
+            int filled = fill(_buffer);
+            if (filled < 0)
+                _eof = true;
+            return filled;
+        }
+    };
+    private final StubEndPoint endPoint;
+    private final Duration timeout;
+
+    RawConnection(StubEndPoint endPoint, Duration timeout)
+    {
+        this.endPoint = endPoint;
+        this.timeout = timeout;
+    }
+
+    /**
+     * @return the client side endpoint, that can be used to slow down or cut the bytes sent
+     */
+    public StubEndPoint getEndPoint()
+    {
+        return endPoint;
+    }
+
+    /**
+     * @param bytes the bytes to send, as ISO-8859-1 characters
+     * @throws IOException if the connection is closed
+     */
+    public void send(String bytes) throws IOException
+    {
+        send(bytes.getBytes(StandardCharsets.ISO_8859_1));
+    }
+
+    /**
+     * @param bytes the bytes to send
+     * @throws IOException if the connection is closed
+     */
+    public void send(byte[] bytes) throws IOException
+    {
+        send(ByteBuffer.wrap(bytes));
+    }
+
+    /**
+     * @param buffers the bytes to send
+     * @throws IOException if the connection is closed
+     */
+    public void send(ByteBuffer... buffers) throws IOException
+    {
+        endPoint.flush(buffers);
+    }
+
+    /**
+     * <p>Half closes the connection, so that the server reads EOF after the bytes sent.</p>
+     */
+    public void shutdownOutput()
+    {
+        endPoint.shutdownOutput();
+    }
+
+    /**
+     * <p>Reads the next HTTP/1 response, which may be an interim response.</p>
+     * <p>The content of a response without {@code Content-Length} nor chunked encoding is
+     * read until EOF; since the parser does not know the request method, a response to a
+     * {@code HEAD} request must instead be read with {@link #readResponseHead()}.</p>
+     *
+     * @return the response, or null if the connection was closed before a complete response
+     * @throws IOException if the response cannot be read
+     */
+    public HttpTester.Response readResponse() throws IOException
+    {
+        HttpTester.Response response = HttpTester.parseResponse(input);
+        input.takeHttpParser();
+        return response;
+    }
+
+    /**
+     * <p>Reads the status line and the headers of the next HTTP/1 response.</p>
+     *
+     * @return the response without content, or null if the connection was closed before the headers
+     * @throws IOException if the response cannot be read
+     */
+    public HttpTester.Response readResponseHead() throws IOException
+    {
+        HttpTester.Response response = new HttpTester.Response()
+        {
+            @Override
+            public boolean headerComplete()
+            {
+                super.headerComplete();
+                return messageComplete();
+            }
+        };
+        HttpTester.parseResponse(input, response);
+        input.takeHttpParser();
+        return response.isComplete() ? response : null;
+    }
+
+    /**
+     * <p>Waits for the server to close the connection, discarding the bytes received.</p>
+     *
+     * @return whether the connection was closed within the tester timeout
+     * @throws IOException if the bytes cannot be read
+     */
+    public boolean awaitClosed() throws IOException
+    {
+        BufferUtil.clear(input.getBuffer());
+        ByteBuffer buffer = BufferUtil.allocate(1024);
+        try
+        {
+            while (true)
+            {
+                BufferUtil.clear(buffer);
+                if (fill(buffer) < 0)
+                    return true;
+            }
+        }
+        catch (SocketTimeoutException x)
+        {
+            return false;
+        }
+    }
+
+    /**
+     * <p>Appends the available bytes to the given buffer, waiting for bytes if none are available.</p>
+     *
+     * @param buffer the buffer to fill, in flush mode
+     * @return the number of bytes read, or -1 at EOF
+     * @throws SocketTimeoutException if no bytes arrive within the tester timeout
+     * @throws IOException if the bytes cannot be read
+     */
+    protected int fill(ByteBuffer buffer) throws IOException
+    {
+        long deadline = System.nanoTime() + timeout.toNanos();
+        while (true)
+        {
+            // A reset closes the endpoint, which is then like EOF.
+            if (!endPoint.isOpen())
+                return -1;
+            int filled = endPoint.fill(buffer);
+            if (filled != 0)
+                return filled;
+
+            FutureCallback callback = new FutureCallback();
+            endPoint.fillInterested(callback);
+            try
+            {
+                callback.get(Math.max(0, deadline - System.nanoTime()), TimeUnit.NANOSECONDS);
+            }
+            catch (TimeoutException x)
+            {
+                throw new SocketTimeoutException("No bytes received in " + timeout.toMillis() + " ms");
+            }
+            catch (InterruptedException x)
+            {
+                throw new InterruptedIOException();
+            }
+            catch (ExecutionException x)
+            {
+                if (!endPoint.isOpen())
+                    return -1;
+                throw new IOException(x.getCause());
+            }
+        }
+    }
+
+    @Override
+    public void close()
+    {
+        endPoint.close();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), endPoint);
+    }
+}
@@ -0,0 +1,246 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.ArrayDeque;
+import java.util.HashMap;
+import java.util.Queue;
+
+import org.eclipse.jetty.client.stub.StubEndPoint;
+import org.eclipse.jetty.http.HostPortHttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http2.frames.DataFrame;
+import org.eclipse.jetty.http2.frames.Frame;
+import org.eclipse.jetty.http2.frames.GoAwayFrame;
+import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.http2.frames.PingFrame;
+import org.eclipse.jetty.http2.frames.PrefaceFrame;
+import org.eclipse.jetty.http2.frames.PriorityFrame;
+import org.eclipse.jetty.http2.frames.PushPromiseFrame;
+import org.eclipse.jetty.http2.frames.ResetFrame;
+import org.eclipse.jetty.http2.frames.SettingsFrame;
+import org.eclipse.jetty.http2.frames.WindowUpdateFrame;
+import org.eclipse.jetty.http2.generator.Generator;
+import org.eclipse.jetty.http2.hpack.HpackException;
+import org.eclipse.jetty.http2.parser.Parser;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+
+/**
+ * <p>A raw connection to a {@link HandlerTester} that speaks HTTP/2 with prior knowledge,
+ * on which the test writes frames, possibly invalid or in an invalid order, and reads
+ * back the frames sent by the server.</p>
+ * <pre>
+ * try (RawHTTP2Connection connection = tester.connectHTTP2())
+ * {
+ *     connection.sendPreface();
+ *     connection.send(new HeadersFrame(1, RawHTTP2Connection.newRequest("GET", "/", HttpFields.EMPTY), null, true));
+ *     HeadersFrame response = connection.readFrame(HeadersFrame.class);
+ * }
+ * </pre>
+ * <p>Bytes that cannot be produced by the frame generator, such as a frame
+ * with an invalid length, can be written with {@link #send(ByteBuffer...)}.</p>
+ */
+public class RawHTTP2Connection extends RawConnection
+{
+    private final Queue<Frame> frames = new ArrayDeque<>();
+    private final ByteBuffer buffer = BufferUtil.allocate(16 * 1024);
+    private final ByteBufferPool byteBufferPool;
+    private final Generator generator;
+    private final Parser parser;
+    private String failure;
+
+    RawHTTP2Connection(StubEndPoint endPoint, Duration timeout, ByteBufferPool byteBufferPool)
+    {
+        super(endPoint, timeout);
+        this.byteBufferPool = byteBufferPool;
+        this.generator = new Generator(byteBufferPool);
+        this.parser = new Parser(byteBufferPool, 8192);
+        this.parser.init(new FrameListener());
+    }
+
+    /**
+     * <p>Creates the metadata of a request, to be sent in a {@link HeadersFrame}.</p>
+     *
+     * @param method the request method
+     * @param target the request target, such as {@code /path?query}
+     * @param fields the request headers
+     * @return the request metadata
+     */
+    public static MetaData.Request newRequest(String method, String target, HttpFields fields)
+    {
+        return new MetaData.Request(method, HttpScheme.HTTP.asString(), new HostPortHttpField("localhost"), target, HttpVersion.HTTP_2, fields, -1);
+    }
+
+    /**
+     * <p>Sends the client connection preface followed by an empty SETTINGS frame.</p>
+     *
+     * @throws IOException if the connection is closed
+     */
+    public void sendPreface() throws IOException
+    {
+        send(new PrefaceFrame(), new SettingsFrame(new HashMap<>(), false));
+    }
+
+    /**
+     * <p>Generates and sends the given frames.</p>
+     *
+     * @param frames the frames to send
+     * @throws IOException if the connection is closed or the headers cannot be encoded
+     */
+    public void send(Frame... frames) throws IOException
+    {
+        ByteBufferPool.Lease lease = new ByteBufferPool.Lease(byteBufferPool);
+        try
+        {
+            for (Frame frame : frames)
+            {
+                if (frame instanceof DataFrame)
+                {
+                    DataFrame data = (DataFrame)frame;
+                    generator.data(lease, data, data.remaining());
+                }
+                else
+                {
+                    generator.control(lease, frame);
+                }
+            }
+            send(lease.getByteBuffers().toArray(new ByteBuffer[0]));
+        }
+        catch (HpackException x)
+        {
+            throw new IOException(x);
+        }
+        finally
+        {
+            lease.recycle();
+        }
+    }
+
+    /**
+     * <p>Reads the next frame sent by the server.</p>
+     *
+     * @return the next frame, or null if the connection was closed
+     * @throws IOException if the frame cannot be read or parsed
+     */
+    public Frame readFrame() throws IOException
+    {
+        while (frames.isEmpty())
+        {
+            if (failure != null)
+                throw new IOException(failure);
+            BufferUtil.clear(buffer);
+            if (fill(buffer) < 0)
+                return null;
+            parser.parse(buffer);
+        }
+        return frames.poll();
+    }
+
+    /**
+     * <p>Reads frames until one of the given type, discarding the frames of other types.</p>
+     *
+     * @param type the type of the frame to read
+     * @param <T> the type of the frame to read
+     * @return the next frame of the given type, or null if the connection was closed before
+     * @throws IOException if the frames cannot be read or parsed
+     */
+    public <T extends Frame> T readFrame(Class<T> type) throws IOException
+    {
+        while (true)
+        {
+            Frame frame = readFrame();
+            if (frame == null)
+                return null;
+            if (type.isInstance(frame))
+                return type.cast(frame);
+        }
+    }
+
+    private class FrameListener extends Parser.Listener.Adapter
+    {
+        @Override
+        public void onData(DataFrame frame)
+        {
+            // The frame data is a slice of the read buffer, which is reused.
+            frames.offer(new DataFrame(frame.getStreamId(), BufferUtil.copy(frame.getData()), frame.isEndStream(), frame.padding()));
+        }
+
+        @Override
+        public void onHeaders(HeadersFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onPriority(PriorityFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onReset(ResetFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onSettings(SettingsFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onPushPromise(PushPromiseFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onPing(PingFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onGoAway(GoAwayFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onWindowUpdate(WindowUpdateFrame frame)
+        {
+            frames.offer(frame);
+        }
+
+        @Override
+        public void onStreamFailure(int streamId, int error, String reason)
+        {
+            failure = String.format("Invalid frame on stream #%d: %d/%s", streamId, error, reason);
+        }
+
+        @Override
+        public void onConnectionFailure(int error, String reason)
+        {
+            failure = String.format("Invalid frames: %d/%s", error, reason);
+        }
+    }
+}
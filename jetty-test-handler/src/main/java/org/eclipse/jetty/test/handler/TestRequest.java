@@ -0,0 +1,165 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.nio.charset.Charset;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.TimeoutException;
+
+import org.eclipse.jetty.client.HttpResponse;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.api.Request;
+import org.eclipse.jetty.client.util.BytesRequestContent;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpHeaderValue;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http.MimeTypes;
+
+/**
+ * <p>A request built by {@link HandlerTester#request(HttpVersion, String, String)}.</p>
+ * <p>Over HTTP/1.1 a request with {@link #trailer(String, String) trailers} is sent
+ * with chunked content, while over HTTP/2 the trailers are sent in a last HEADERS frame.</p>
+ */
+public class TestRequest
+{
+    private final HttpFields.Mutable headers = HttpFields.build();
+    private final HandlerTester tester;
+    private final HttpVersion version;
+    private final String method;
+    private final String target;
+    private HttpFields.Mutable trailers;
+    private String contentType;
+    private byte[] content;
+
+    TestRequest(HandlerTester tester, HttpVersion version, String method, String target)
+    {
+        this.tester = tester;
+        this.version = version;
+        this.method = method;
+        this.target = target;
+    }
+
+    /**
+     * @param name the header name
+     * @param value the header value
+     * @return this request
+     */
+    public TestRequest header(String name, String value)
+    {
+        headers.add(name, value);
+        return this;
+    }
+
+    /**
+     * @param header the header
+     * @param value the header value
+     * @return this request
+     */
+    public TestRequest header(HttpHeader header, String value)
+    {
+        headers.add(header, value);
+        return this;
+    }
+
+    /**
+     * @param contentType the request content type
+     * @param content the request content, encoded with the charset of the content type or UTF-8
+     * @return this request
+     */
+    public TestRequest content(String contentType, String content)
+    {
+        String charset = MimeTypes.getCharsetFromContentType(contentType);
+        return content(contentType, content.getBytes(charset == null ? StandardCharsets.UTF_8 : Charset.forName(charset)));
+    }
+
+    /**
+     * @param contentType the request content type
+     * @param content the request content
+     * @return this request
+     */
+    public TestRequest content(String contentType, byte[] content)
+    {
+        this.contentType = contentType;
+        this.content = content;
+        return this;
+    }
+
+    /**
+     * @param name the trailer name
+     * @param value the trailer value
+     * @return this request
+     */
+    public TestRequest trailer(String name, String value)
+    {
+        if (trailers == null)
+            trailers = HttpFields.build();
+        trailers.add(name, value);
+        return this;
+    }
+
+    /**
+     * <p>Adds the {@code Expect: 100-continue} header, so that the content is only
+     * sent after the handler has started to read it.</p>
+     *
+     * @return this request
+     */
+    public TestRequest expect100Continue()
+    {
+        return header(HttpHeader.EXPECT, HttpHeaderValue.CONTINUE.asString());
+    }
+
+    /**
+     * <p>Sends this request and waits for the response.</p>
+     *
+     * @return the response
+     * @throws InterruptedException if the wait is interrupted
+     * @throws TimeoutException if the response does not arrive within the {@link HandlerTester#getTimeout() timeout}
+     * @throws ExecutionException if the exchange fails
+     */
+    public TestResponse send() throws InterruptedException, TimeoutException, ExecutionException
+    {
+        List<MetaData.Response> interimResponses = new ArrayList<>();
+        Request request = tester.getHttpClient().newRequest("http://localhost" + target)
+            .method(method)
+            .version(version)
+            .timeout(tester.getTimeout().toMillis(), TimeUnit.MILLISECONDS)
+            .headers(fields -> fields.add(headers))
+            .onResponseSuccess(response ->
+            {
+                if (HttpStatus.isInterim(response.getStatus()))
+                {
+                    interimResponses.add(new MetaData.Response(response.getVersion(), response.getStatus(), response.getReason(), response.getHeaders().asImmutable(), -1));
+                    // The client does not reset the headers between an interim and the final response.
+                    if (response instanceof HttpResponse)
+                        ((HttpResponse)response).clearHeaders();
+                }
+            });
+        if (content != null)
+            request.body(new BytesRequestContent(contentType, content));
+        if (trailers != null)
+        {
+            HttpFields requestTrailers = trailers.asImmutable();
+            request.trailers(() -> requestTrailers);
+        }
+        ContentResponse response = request.send();
+        return new TestResponse(response, interimResponses);
+    }
+}
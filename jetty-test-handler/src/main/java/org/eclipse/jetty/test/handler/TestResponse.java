@@ -0,0 +1,104 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.util.List;
+
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+
+/**
+ * <p>The response to a {@link TestRequest}, with the interim responses that preceded it.</p>
+ */
+public class TestResponse
+{
+    private final ContentResponse response;
+    private final List<MetaData.Response> interimResponses;
+
+    TestResponse(ContentResponse response, List<MetaData.Response> interimResponses)
+    {
+        this.response = response;
+        this.interimResponses = List.copyOf(interimResponses);
+    }
+
+    public HttpVersion getVersion()
+    {
+        return response.getVersion();
+    }
+
+    public int getStatus()
+    {
+        return response.getStatus();
+    }
+
+    public HttpFields getHeaders()
+    {
+        return response.getHeaders();
+    }
+
+    /**
+     * @param name the header name
+     * @return the first value of the header, or null if the header is absent
+     */
+    public String get(String name)
+    {
+        return response.getHeaders().get(name);
+    }
+
+    /**
+     * @return the response trailers, or null if the response had no trailers
+     */
+    public HttpFields getTrailers()
+    {
+        return response.getTrailers();
+    }
+
+    public byte[] getContent()
+    {
+        return response.getContent();
+    }
+
+    public String getContentAsString()
+    {
+        return response.getContentAsString();
+    }
+
+    /**
+     * <p>Returns the 1xx responses received before this response, such as
+     * {@code 102 Processing} or {@code 103 Early Hints}; {@code 100 Continue}
+     * responses are consumed by the client and are not reported.</p>
+     *
+     * @return the interim responses, in the order they were received
+     */
+    public List<MetaData.Response> getInterimResponses()
+    {
+        return interimResponses;
+    }
+
+    /**
+     * @return the client response
+     */
+    public ContentResponse getContentResponse()
+    {
+        return response;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s %d,interim=%d]", getClass().getSimpleName(), hashCode(), getVersion(), getStatus(), interimResponses.size());
+    }
+}
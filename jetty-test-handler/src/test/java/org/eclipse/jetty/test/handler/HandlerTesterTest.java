@@ -0,0 +1,223 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.test.handler;
+
+import java.io.IOException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.http.HttpVersion;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http2.ErrorCode;
+import org.eclipse.jetty.http2.frames.DataFrame;
+import org.eclipse.jetty.http2.frames.GoAwayFrame;
+import org.eclipse.jetty.http2.frames.HeadersFrame;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.IO;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.MethodSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.hamcrest.Matchers.nullValue;
+
+public class HandlerTesterTest
+{
+    private HandlerTester tester;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        tester = new HandlerTester(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                switch (target)
+                {
+                    case "/echo":
+                    {
+                        String content = IO.toString(request.getInputStream());
+                        HttpFields trailers = baseRequest.getTrailerHttpFields();
+                        String checksum = trailers == null ? null : trailers.get("Checksum");
+                        baseRequest.getResponse().setTrailers(() -> HttpFields.build().put("Echo-Checksum", checksum));
+                        response.setContentType("text/plain");
+                        response.getWriter().print(content);
+                        break;
+                    }
+                    case "/reject":
+                    {
+                        response.setStatus(HttpStatus.EXPECTATION_FAILED_417);
+                        break;
+                    }
+                    case "/hints":
+                    {
+                        baseRequest.getResponse().sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</style.css>; rel=preload"));
+                        response.setContentType("text/plain");
+                        response.getWriter().print("hinted");
+                        break;
+                    }
+                    case "/processing":
+                    {
+                        baseRequest.getResponse().sendProcessing();
+                        response.setStatus(HttpStatus.NO_CONTENT_204);
+                        break;
+                    }
+                    default:
+                    {
+                        response.setStatus(HttpStatus.OK_200);
+                        break;
+                    }
+                }
+            }
+        });
+        tester.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        tester.stop();
+    }
+
+    @ParameterizedTest
+    @MethodSource("org.eclipse.jetty.test.handler.HandlerTester#versions")
+    public void testTrailersWithExpect100Continue(HttpVersion version) throws Exception
+    {
+        TestResponse response = tester.request(version, "POST", "/echo")
+            .content("text/plain", "hello")
+            .trailer("Checksum", "5d41402a")
+            .expect100Continue()
+            .send();
+
+        assertThat(response.getVersion(), is(version));
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContentAsString(), is("hello"));
+        assertThat(response.getTrailers().get("Echo-Checksum"), is("5d41402a"));
+    }
+
+    @ParameterizedTest
+    @MethodSource("org.eclipse.jetty.test.handler.HandlerTester#versions")
+    public void testExpect100ContinueRejected(HttpVersion version) throws Exception
+    {
+        TestResponse response = tester.request(version, "POST", "/reject")
+            .content("text/plain", "never read")
+            .expect100Continue()
+            .send();
+
+        assertThat(response.getStatus(), is(HttpStatus.EXPECTATION_FAILED_417));
+    }
+
+    @ParameterizedTest
+    @MethodSource("org.eclipse.jetty.test.handler.HandlerTester#versions")
+    public void testEarlyHints(HttpVersion version) throws Exception
+    {
+        TestResponse response = tester.request(version, "GET", "/hints").send();
+
+        assertThat(response.getInterimResponses().size(), is(1));
+        MetaData.Response hints = response.getInterimResponses().get(0);
+        assertThat(hints.getStatus(), is(HttpStatus.EARLY_HINT_103));
+        assertThat(hints.getFields().get(HttpHeader.LINK), is("</style.css>; rel=preload"));
+        // The hints are not part of the final response.
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get(HttpHeader.LINK.asString()), nullValue());
+        assertThat(response.getContentAsString(), is("hinted"));
+    }
+
+    @Test
+    public void testProcessingOverRawHTTP1() throws Exception
+    {
+        try (RawConnection connection = tester.connect())
+        {
+            connection.send("GET /processing HTTP/1.1\r\nHost: localhost\r\nExpect: 102-processing\r\n\r\n");
+
+            HttpTester.Response interim = connection.readResponse();
+            assertThat(interim.getStatus(), is(HttpStatus.PROCESSING_102));
+            HttpTester.Response response = connection.readResponse();
+            assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+        }
+    }
+
+    @Test
+    public void testMalformedHTTP1() throws Exception
+    {
+        try (RawConnection connection = tester.connect())
+        {
+            // Pipelined requests, the second with a duplicate Content-Length.
+            connection.send("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+                "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 1\r\nContent-Length: 2\r\n\r\nxx");
+
+            assertThat(connection.readResponse().getStatus(), is(HttpStatus.OK_200));
+            assertThat(connection.readResponse().getStatus(), is(HttpStatus.BAD_REQUEST_400));
+            assertThat(connection.awaitClosed(), is(true));
+        }
+    }
+
+    @Test
+    public void testHalfClosedHTTP1() throws Exception
+    {
+        try (RawConnection connection = tester.connect())
+        {
+            connection.send("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n");
+            connection.shutdownOutput();
+
+            // The server responds, then closes the connection since it read EOF.
+            assertThat(connection.readResponse().getStatus(), is(HttpStatus.OK_200));
+            assertThat(connection.awaitClosed(), is(true));
+        }
+    }
+
+    @Test
+    public void testRawHTTP2() throws Exception
+    {
+        try (RawHTTP2Connection connection = tester.connectHTTP2())
+        {
+            connection.sendPreface();
+            connection.send(new HeadersFrame(1, RawHTTP2Connection.newRequest("POST", "/echo", HttpFields.EMPTY), null, false),
+                new DataFrame(1, BufferUtil.toBuffer("hello"), true));
+
+            HeadersFrame headers = connection.readFrame(HeadersFrame.class);
+            assertThat(((MetaData.Response)headers.getMetaData()).getStatus(), is(HttpStatus.OK_200));
+            DataFrame data = connection.readFrame(DataFrame.class);
+            assertThat(BufferUtil.toString(data.getData()), is("hello"));
+        }
+    }
+
+    @Test
+    public void testMalformedHTTP2() throws Exception
+    {
+        try (RawHTTP2Connection connection = tester.connectHTTP2())
+        {
+            connection.sendPreface();
+            // Clients must use odd stream ids.
+            connection.send(new HeadersFrame(2, RawHTTP2Connection.newRequest("GET", "/", HttpFields.EMPTY), null, true));
+
+            GoAwayFrame goAway = connection.readFrame(GoAwayFrame.class);
+            assertThat(goAway, notNullValue());
+            assertThat(goAway.getError(), is(ErrorCode.PROTOCOL_ERROR.code));
+            assertThat(connection.awaitClosed(), is(true));
+        }
+    }
+}
@@ -35,6 +35,7 @@ import org.eclipse.jetty.server.HttpConnectionFactory;
 import org.eclipse.jetty.server.ProxyConnectionFactory;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
 import org.eclipse.jetty.server.handler.AbstractHandler;
 import org.eclipse.jetty.toolchain.test.FS;
 import org.eclipse.jetty.util.component.LifeCycle;
@@ -258,6 +259,53 @@ public class UnixDomainTest
         }
     }
 
+    @Test
+    public void testPerRequestUnixDomainPathWithTCP() throws Exception
+    {
+        start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                jettyRequest.setHandled(true);
+                SocketAddress local = jettyRequest.getHttpChannel().getEndPoint().getLocalSocketAddress();
+                boolean unixDomain = unixDomainSocketAddressClass.isInstance(local);
+                response.setHeader("X-Unix-Domain", String.valueOf(unixDomain));
+            }
+        });
+        ServerConnector tcpConnector = new ServerConnector(server, 1, 1);
+        server.addConnector(tcpConnector);
+        tcpConnector.start();
+
+        // A single HttpClient with the default transport mixes TCP and Unix-Domain destinations.
+        HttpClient httpClient = new HttpClient();
+        httpClient.start();
+        try
+        {
+            String uri = "http://localhost:" + tcpConnector.getLocalPort() + "/path";
+
+            ContentResponse response = httpClient.newRequest(uri)
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("false", response.getHeaders().get("X-Unix-Domain"));
+
+            response = httpClient.newRequest(uri)
+                .unixDomainPath(unixDomainPath)
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("true", response.getHeaders().get("X-Unix-Domain"));
+
+            // The Unix-Domain path identifies a different destination.
+            assertEquals(2, httpClient.getDestinations().size());
+        }
+        finally
+        {
+            httpClient.stop();
+        }
+    }
+
     @Test
     public void testInvalidUnixDomainPath()
     {
@@ -0,0 +1,546 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ajax;
+
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.regex.Pattern;
+
+/**
+ * <p>A parser for the subset of YAML that is needed by configuration documents,
+ * such as pipeline configurations and authorization policies.</p>
+ * <p>The supported subset is:</p>
+ * <ul>
+ * <li>block mappings and block sequences, indented with spaces;</li>
+ * <li>sequence entries that start a mapping, such as {@code - type: gzip};</li>
+ * <li>flow sequences and flow mappings, such as {@code [GET, POST]} or {@code {a: 1}};</li>
+ * <li>plain, single quoted and double quoted scalars;</li>
+ * <li>comments and an optional {@code ---} document start marker.</li>
+ * </ul>
+ * <p>Plain scalars are resolved with the YAML core schema: {@code null} and {@code ~} are
+ * null, {@code true} and {@code false} are booleans, and numbers are {@link Long}s or
+ * {@link Double}s; quoted scalars are always strings.
+ * Anchors, aliases, tags, block scalars, complex keys and multiple documents are not
+ * supported and are reported as errors.</p>
+ * <p>Mappings are parsed as {@link Map}s that preserve the order of the keys,
+ * and sequences as {@link List}s.</p>
+ */
+public class YamlParser
+{
+    private static final Pattern INTEGER = Pattern.compile("[-+]?[0-9]+");
+    private static final Pattern FLOAT = Pattern.compile("[-+]?(\\.[0-9]+|[0-9]+(\\.[0-9]*)?)([eE][-+]?[0-9]+)?");
+
+    private final List<Line> lines = new ArrayList<>();
+    private int index;
+
+    private YamlParser(String yaml)
+    {
+        String[] split = yaml.split("\r?\n|\r", -1);
+        for (int i = 0; i < split.length; ++i)
+        {
+            String text = split[i];
+            int number = i + 1;
+            int indent = 0;
+            while (indent < text.length() && text.charAt(indent) == ' ')
+            {
+                ++indent;
+            }
+            String content = stripComment(text.substring(indent), number).trim();
+            if (content.isEmpty())
+                continue;
+            if (text.charAt(indent) == '\t')
+                throw error(number, "tabs cannot be used for indentation");
+            if (lines.isEmpty() && content.equals("---"))
+                continue;
+            if (content.equals("---") || content.equals("..."))
+                throw error(number, "multiple documents are not supported");
+            if (content.startsWith("%"))
+                throw error(number, "directives are not supported");
+            lines.add(new Line(number, indent, content));
+        }
+    }
+
+    /**
+     * @param yaml the YAML document
+     * @return the value of the document: a Map, a List, a scalar or null if the document is empty
+     * @throws IllegalArgumentException if the document is invalid or uses unsupported features
+     */
+    public static Object parse(String yaml)
+    {
+        YamlParser parser = new YamlParser(yaml);
+        if (parser.lines.isEmpty())
+            return null;
+        Line first = parser.lines.get(0);
+        if (first.indent != 0)
+            throw error(first.number, "unexpected indentation");
+        Object result = parser.parseBlock(0);
+        if (parser.index < parser.lines.size())
+            throw error(parser.lines.get(parser.index).number, "unexpected content");
+        return result;
+    }
+
+    private Object parseBlock(int indent)
+    {
+        Line line = lines.get(index);
+        if (line.isSequenceEntry())
+            return parseSequence(indent);
+        if (findMappingSeparator(line.content, line.number) >= 0)
+            return parseMapping(indent);
+        ++index;
+        return parseValue(line.content, line.number);
+    }
+
+    private Map<String, Object> parseMapping(int indent)
+    {
+        Map<String, Object> mapping = new LinkedHashMap<>();
+        while (index < lines.size())
+        {
+            Line line = lines.get(index);
+            if (line.indent < indent)
+                break;
+            if (line.indent > indent)
+                throw error(line.number, "unexpected indentation");
+            if (line.isSequenceEntry())
+                throw error(line.number, "unexpected sequence entry in a mapping");
+            int separator = findMappingSeparator(line.content, line.number);
+            if (separator < 0)
+                throw error(line.number, "expected a mapping entry 'key: value'");
+            String key = parseKey(line.content.substring(0, separator).trim(), line.number);
+            if (mapping.containsKey(key))
+                throw error(line.number, "duplicate key '" + key + "'");
+            String rest = line.content.substring(separator + 1).trim();
+            ++index;
+            Object value;
+            if (rest.isEmpty())
+            {
+                value = null;
+                if (index < lines.size())
+                {
+                    Line next = lines.get(index);
+                    if (next.indent > indent)
+                        value = parseBlock(next.indent);
+                    else if (next.indent == indent && next.isSequenceEntry())
+                        value = parseSequence(indent);
+                }
+            }
+            else
+            {
+                value = parseValue(rest, line.number);
+            }
+            mapping.put(key, value);
+        }
+        return mapping;
+    }
+
+    private List<Object> parseSequence(int indent)
+    {
+        List<Object> sequence = new ArrayList<>();
+        while (index < lines.size())
+        {
+            Line line = lines.get(index);
+            if (line.indent < indent || (line.indent == indent && !line.isSequenceEntry()))
+                break;
+            if (line.indent > indent)
+                throw error(line.number, "unexpected indentation");
+            int offset = 1;
+            while (offset < line.content.length() && line.content.charAt(offset) == ' ')
+            {
+                ++offset;
+            }
+            String rest = line.content.substring(offset);
+            if (rest.isEmpty())
+            {
+                ++index;
+                Object value = null;
+                if (index < lines.size() && lines.get(index).indent > indent)
+                    value = parseBlock(lines.get(index).indent);
+                sequence.add(value);
+            }
+            else if (rest.startsWith("- ") || rest.equals("-") || findMappingSeparator(rest, line.number) >= 0)
+            {
+                // The entry starts a nested block, whose indentation
+                // is the column of the first character after the dash.
+                int nested = indent + offset;
+                lines.set(index, new Line(line.number, nested, rest));
+                sequence.add(parseBlock(nested));
+            }
+            else
+            {
+                ++index;
+                sequence.add(parseValue(rest, line.number));
+            }
+        }
+        return sequence;
+    }
+
+    private static String parseKey(String key, int number)
+    {
+        if (key.isEmpty())
+            throw error(number, "empty key");
+        if (key.startsWith("?"))
+            throw error(number, "complex keys are not supported");
+        checkSupported(key, number);
+        if (key.startsWith("\"") || key.startsWith("'"))
+        {
+            Cursor cursor = new Cursor(key, number);
+            String result = cursor.parseQuoted();
+            cursor.expectEnd();
+            return result;
+        }
+        return key;
+    }
+
+    private static Object parseValue(String text, int number)
+    {
+        checkSupported(text, number);
+        char c = text.charAt(0);
+        if (c != '[' && c != '{' && c != '"' && c != '\'')
+            return resolve(text);
+        Cursor cursor = new Cursor(text, number);
+        Object value = cursor.parseFlow();
+        cursor.expectEnd();
+        return value;
+    }
+
+    private static void checkSupported(String text, int number)
+    {
+        switch (text.charAt(0))
+        {
+            case '&':
+            case '*':
+                throw error(number, "anchors and aliases are not supported");
+            case '!':
+                throw error(number, "tags are not supported");
+            case '|':
+            case '>':
+                throw error(number, "block scalars are not supported");
+            case '@':
+            case '`':
+                throw error(number, "reserved indicator '" + text.charAt(0) + "'");
+            default:
+                break;
+        }
+    }
+
+    private static Object resolve(String plain)
+    {
+        switch (plain)
+        {
+            case "":
+            case "~":
+            case "null":
+            case "Null":
+            case "NULL":
+                return null;
+            case "true":
+            case "True":
+            case "TRUE":
+                return Boolean.TRUE;
+            case "false":
+            case "False":
+            case "FALSE":
+                return Boolean.FALSE;
+            default:
+                break;
+        }
+        try
+        {
+            if (INTEGER.matcher(plain).matches())
+                return Long.parseLong(plain);
+            if (FLOAT.matcher(plain).matches())
+                return Double.parseDouble(plain);
+        }
+        catch (NumberFormatException x)
+        {
+            // Too large for a Long, keep it as a string.
+        }
+        return plain;
+    }
+
+    /**
+     * @return the index of the ':' that separates a key from its value, or -1 if there is none
+     */
+    private static int findMappingSeparator(String content, int number)
+    {
+        char first = content.charAt(0);
+        if (first == '[' || first == '{')
+            return -1;
+        int i = 0;
+        if (first == '"' || first == '\'')
+        {
+            Cursor cursor = new Cursor(content, number);
+            cursor.parseQuoted();
+            i = cursor.position;
+        }
+        for (; i < content.length(); ++i)
+        {
+            if (content.charAt(i) == ':' && (i + 1 == content.length() || content.charAt(i + 1) == ' '))
+                return i;
+        }
+        return -1;
+    }
+
+    private static String stripComment(String text, int number)
+    {
+        char quote = 0;
+        for (int i = 0; i < text.length(); ++i)
+        {
+            char c = text.charAt(i);
+            if (quote == 0)
+            {
+                if (c == '#' && (i == 0 || text.charAt(i - 1) == ' ' || text.charAt(i - 1) == '\t'))
+                    return text.substring(0, i);
+                // Quotes only start a quoted scalar at the beginning of a token.
+                if ((c == '"' || c == '\'') && (i == 0 || " [{,:-".indexOf(text.charAt(i - 1)) >= 0))
+                    quote = c;
+            }
+            else if (quote == '"' && c == '\\')
+            {
+                ++i;
+            }
+            else if (c == quote)
+            {
+                if (quote == '\'' && i + 1 < text.length() && text.charAt(i + 1) == '\'')
+                    ++i;
+                else
+                    quote = 0;
+            }
+        }
+        if (quote != 0)
+            throw error(number, "unterminated quoted scalar");
+        return text;
+    }
+
+    private static IllegalArgumentException error(int number, String message)
+    {
+        return new IllegalArgumentException("Invalid YAML at line " + number + ": " + message);
+    }
+
+    private static class Line
+    {
+        private final int number;
+        private final int indent;
+        private final String content;
+
+        private Line(int number, int indent, String content)
+        {
+            this.number = number;
+            this.indent = indent;
+            this.content = content;
+        }
+
+        private boolean isSequenceEntry()
+        {
+            return content.equals("-") || content.startsWith("- ");
+        }
+    }
+
+    /**
+     * <p>Parses the flow collections and the quoted scalars of a single line.</p>
+     */
+    private static class Cursor
+    {
+        private final String text;
+        private final int number;
+        private int position;
+
+        private Cursor(String text, int number)
+        {
+            this.text = text;
+            this.number = number;
+        }
+
+        private Object parseFlow()
+        {
+            skipSpaces();
+            if (position == text.length())
+                throw error(number, "unexpected end of line");
+            char c = text.charAt(position);
+            switch (c)
+            {
+                case '[':
+                    return parseFlowSequence();
+                case '{':
+                    return parseFlowMapping();
+                case '"':
+                case '\'':
+                    return parseQuoted();
+                default:
+                    checkSupported(text.substring(position), number);
+                    return resolve(parsePlain(false));
+            }
+        }
+
+        private List<Object> parseFlowSequence()
+        {
+            List<Object> sequence = new ArrayList<>();
+            ++position;
+            skipSpaces();
+            if (peek() == ']')
+            {
+                ++position;
+                return sequence;
+            }
+            while (true)
+            {
+                sequence.add(parseFlow());
+                skipSpaces();
+                char c = next();
+                if (c == ']')
+                    return sequence;
+                if (c != ',')
+                    throw error(number, "expected ',' or ']' in flow sequence");
+            }
+        }
+
+        private Map<String, Object> parseFlowMapping()
+        {
+            Map<String, Object> mapping = new LinkedHashMap<>();
+            ++position;
+            skipSpaces();
+            if (peek() == '}')
+            {
+                ++position;
+                return mapping;
+            }
+            while (true)
+            {
+                skipSpaces();
+                char c = peek();
+                String key = c == '"' || c == '\'' ? parseQuoted() : parsePlain(true);
+                if (key.isEmpty())
+                    throw error(number, "empty key in flow mapping");
+                skipSpaces();
+                if (next() != ':')
+                    throw error(number, "expected ':' after key '" + key + "' in flow mapping");
+                if (mapping.containsKey(key))
+                    throw error(number, "duplicate key '" + key + "'");
+                mapping.put(key, parseFlow());
+                skipSpaces();
+                c = next();
+                if (c == '}')
+                    return mapping;
+                if (c != ',')
+                    throw error(number, "expected ',' or '}' in flow mapping");
+            }
+        }
+
+        private String parsePlain(boolean key)
+        {
+            int start = position;
+            while (position < text.length())
+            {
+                char c = text.charAt(position);
+                if (c == ',' || c == ']' || c == '}')
+                    break;
+                if (c == ':' && (key || position + 1 == text.length() || " ,]}".indexOf(text.charAt(position + 1)) >= 0))
+                    break;
+                ++position;
+            }
+            return text.substring(start, position).trim();
+        }
+
+        private String parseQuoted()
+        {
+            char quote = next();
+            StringBuilder builder = new StringBuilder();
+            while (true)
+            {
+                if (position == text.length())
+                    throw error(number, "unterminated quoted scalar");
+                char c = next();
+                if (c == quote)
+                {
+                    if (quote == '\'' && peek() == '\'')
+                    {
+                        ++position;
+                        builder.append('\'');
+                        continue;
+                    }
+                    return builder.toString();
+                }
+                if (quote == '"' && c == '\\')
+                    builder.append(parseEscape());
+                else
+                    builder.append(c);
+            }
+        }
+
+        private char parseEscape()
+        {
+            if (position == text.length())
+                throw error(number, "unterminated escape sequence");
+            char c = next();
+            switch (c)
+            {
+                case '"':
+                case '\\':
+                case '/':
+                    return c;
+                case '0':
+                    return '\0';
+                case 't':
+                    return '\t';
+                case 'n':
+                    return '\n';
+                case 'r':
+                    return '\r';
+                case 'u':
+                    if (position + 4 > text.length())
+                        throw error(number, "invalid unicode escape sequence");
+                    try
+                    {
+                        char result = (char)Integer.parseInt(text.substring(position, position + 4), 16);
+                        position += 4;
+                        return result;
+                    }
+                    catch (NumberFormatException x)
+                    {
+                        throw error(number, "invalid unicode escape sequence");
+                    }
+                default:
+                    throw error(number, "unsupported escape sequence '\\" + c + "'");
+            }
+        }
+
+        private void expectEnd()
+        {
+            skipSpaces();
+            if (position < text.length())
+                throw error(number, "unexpected content '" + text.substring(position) + "'");
+        }
+
+        private void skipSpaces()
+        {
+            while (position < text.length() && text.charAt(position) == ' ')
+            {
+                ++position;
+            }
+        }
+
+        private char peek()
+        {
+            return position < text.length() ? text.charAt(position) : 0;
+        }
+
+        private char next()
+        {
+            if (position == text.length())
+                throw error(number, "unexpected end of line");
+            return text.charAt(position++);
+        }
+    }
+}
@@ -0,0 +1,130 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ajax;
+
+import java.util.List;
+import java.util.Map;
+
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class YamlParserTest
+{
+    @Test
+    public void testBlockCollections()
+    {
+        String yaml = "---\n" +
+            "# Comment.\n" +
+            "pipeline:\n" +
+            "  - type: gzip # Trailing comment.\n" +
+            "    minGzipSize: 1024\n" +
+            "  - type: contexts\n" +
+            "    contexts:\n" +
+            "    - contextPath: /api\n" +
+            "      routes:\n" +
+            "        -\n" +
+            "          proxyTo: http://localhost:8080/\n" +
+            "  -\n" +
+            "  - - nested\n" +
+            "    - sequence\n";
+
+        Map<?, ?> document = (Map<?, ?>)YamlParser.parse(yaml);
+        List<?> pipeline = (List<?>)document.get("pipeline");
+        assertThat(pipeline.size(), is(4));
+        assertThat(pipeline.get(0), is(Map.of("type", "gzip", "minGzipSize", 1024L)));
+        Map<?, ?> contexts = (Map<?, ?>)pipeline.get(1);
+        Map<?, ?> context = (Map<?, ?>)((List<?>)contexts.get("contexts")).get(0);
+        assertThat(context.get("contextPath"), is("/api"));
+        assertThat(context.get("routes"), is(List.of(Map.of("proxyTo", "http://localhost:8080/"))));
+        assertThat(pipeline.get(2), nullValue());
+        assertThat(pipeline.get(3), is(List.of("nested", "sequence")));
+    }
+
+    @Test
+    public void testScalars()
+    {
+        String yaml = "string: hello world\n" +
+            "integer: -42\n" +
+            "float: 1.5e3\n" +
+            "yes: true\n" +
+            "no: False\n" +
+            "nothing: ~\n" +
+            "empty:\n" +
+            "double: \"a \\\"quoted\\\" # string\\n\"\n" +
+            "single: 'it''s: #1'\n" +
+            "\"quoted key\": value\n" +
+            "header: header:X-API-Key\n" +
+            "url: http://host:8080/path\n";
+
+        Map<?, ?> document = (Map<?, ?>)YamlParser.parse(yaml);
+        assertThat(document.get("string"), is("hello world"));
+        assertThat(document.get("integer"), is(-42L));
+        assertThat(document.get("float"), is(1500.0D));
+        assertThat(document.get("yes"), is(true));
+        assertThat(document.get("no"), is(false));
+        assertThat(document.get("nothing"), nullValue());
+        assertThat(document.containsKey("empty"), is(true));
+        assertThat(document.get("empty"), nullValue());
+        assertThat(document.get("double"), is("a \"quoted\" # string\n"));
+        assertThat(document.get("single"), is("it's: #1"));
+        assertThat(document.get("quoted key"), is("value"));
+        assertThat(document.get("header"), is("header:X-API-Key"));
+        assertThat(document.get("url"), is("http://host:8080/path"));
+        // The key order is preserved.
+        assertThat(document.keySet(), contains("string", "integer", "float", "yes", "no", "nothing", "empty", "double", "single", "quoted key", "header", "url"));
+    }
+
+    @Test
+    public void testFlowCollections()
+    {
+        String yaml = "methods: [GET, POST, \"PUT\"]\n" +
+            "empty: []\n" +
+            "origins: [http://a.example.com, 'https://*.example.com']\n" +
+            "mapping: {type: gzip, minGzipSize: 10, paths: [/a, /b], nested: {}}\n";
+
+        Map<?, ?> document = (Map<?, ?>)YamlParser.parse(yaml);
+        assertThat(document.get("methods"), is(List.of("GET", "POST", "PUT")));
+        assertThat(document.get("empty"), is(List.of()));
+        assertThat(document.get("origins"), is(List.of("http://a.example.com", "https://*.example.com")));
+        assertThat(document.get("mapping"), is(Map.of("type", "gzip", "minGzipSize", 10L, "paths", List.of("/a", "/b"), "nested", Map.of())));
+    }
+
+    @ParameterizedTest
+    @ValueSource(strings = {
+        "a: &anchor value\n",
+        "a: *alias\n",
+        "a: !!str value\n",
+        "a: |\n  text\n",
+        "a: 1\n---\nb: 2\n",
+        "a:\n\t- 1\n",
+        "a: 1\n  b: 2\n",
+        "a: 1\na: 2\n",
+        "a: [1, 2\n",
+        "a: \"unterminated\n",
+        "a: 1\n- 2\n"
+    })
+    public void testInvalid(String yaml)
+    {
+        IllegalArgumentException x = assertThrows(IllegalArgumentException.class, () -> YamlParser.parse(yaml));
+        assertThat(x.getMessage(), containsString("Invalid YAML at line"));
+    }
+}
@@ -0,0 +1,442 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util;
+
+import java.io.FilterOutputStream;
+import java.io.IOException;
+import java.io.OutputStream;
+import java.util.Objects;
+import java.util.concurrent.TimeUnit;
+
+/**
+ * <p>An {@link OutputStream} decorator for progressive rendering, that flushes the
+ * wrapped stream only at content boundaries, such as the end of a JSON value or of
+ * an HTML element, and only when a budget is exhausted.</p>
+ * <p>Flushing a response after every write produces many small chunks or frames,
+ * which is particularly inefficient for HTTP/2 and HTTP/3. Never flushing delays the
+ * rendering of the content by the client until the response buffer is full.
+ * This class writes the content through to the wrapped stream, normally a
+ * {@code ServletOutputStream} that aggregates the writes, and flushes it when:</p>
+ * <ul>
+ *   <li>a {@link Boundary} is found, and at least {@link #getFlushBytes() flushBytes}
+ *   were written since the last flush, or at least {@link #getFlushDelay() flushDelay}
+ *   elapsed since the last flush</li>
+ *   <li>{@link #getMaxUnflushedBytes() maxUnflushedBytes} were written since the last
+ *   flush, regardless of boundaries</li>
+ *   <li>{@link #flush()} is called explicitly</li>
+ * </ul>
+ * <p>When a boundary falls in the middle of a write, the bytes up to the boundary
+ * are written and flushed, and then the rest of the bytes are written.</p>
+ * <p>Boundaries are detected on bytes, and only depend on ASCII characters, so
+ * that content encoded in UTF-8 is correctly handled. Characters should be written
+ * via a {@code Writer} that does not buffer, or that is flushed regularly, otherwise
+ * the boundaries are only seen when the {@code Writer} buffer is full.</p>
+ * <p>Instances of this class are not thread safe.</p>
+ */
+public class BoundaryFlushingOutputStream extends FilterOutputStream
+{
+    private final Boundary _boundary;
+    private int _flushBytes = 4096;
+    private long _flushDelay = TimeUnit.MILLISECONDS.toNanos(200);
+    private int _maxUnflushedBytes = 32 * 1024;
+    private long _unflushed;
+    private long _lastFlush = NanoTime.now();
+    private long _flushes;
+
+    /**
+     * @param out the stream to write to
+     * @param boundary the boundary detector
+     */
+    public BoundaryFlushingOutputStream(OutputStream out, Boundary boundary)
+    {
+        super(Objects.requireNonNull(out));
+        _boundary = Objects.requireNonNull(boundary);
+    }
+
+    public Boundary getBoundary()
+    {
+        return _boundary;
+    }
+
+    /**
+     * @return the minimum number of bytes written since the last flush to flush at a boundary
+     */
+    public int getFlushBytes()
+    {
+        return _flushBytes;
+    }
+
+    /**
+     * @param flushBytes the minimum number of bytes written since the last flush to flush at a boundary
+     */
+    public void setFlushBytes(int flushBytes)
+    {
+        _flushBytes = flushBytes;
+    }
+
+    /**
+     * @return the time in milliseconds since the last flush after which a boundary is flushed
+     * regardless of {@link #getFlushBytes() flushBytes}, or a negative value to disable
+     */
+    public long getFlushDelay()
+    {
+        return _flushDelay < 0 ? -1 : TimeUnit.NANOSECONDS.toMillis(_flushDelay);
+    }
+
+    /**
+     * @param flushDelay the time in milliseconds since the last flush after which a boundary is flushed
+     * regardless of {@link #getFlushBytes() flushBytes}, or a negative value to disable
+     */
+    public void setFlushDelay(long flushDelay)
+    {
+        _flushDelay = flushDelay < 0 ? -1 : TimeUnit.MILLISECONDS.toNanos(flushDelay);
+    }
+
+    /**
+     * @return the maximum number of bytes written since the last flush, after which
+     * the stream is flushed even if no boundary was found, or a non-positive value to disable
+     */
+    public int getMaxUnflushedBytes()
+    {
+        return _maxUnflushedBytes;
+    }
+
+    /**
+     * @param maxUnflushedBytes the maximum number of bytes written since the last flush, after which
+     * the stream is flushed even if no boundary was found, or a non-positive value to disable
+     */
+    public void setMaxUnflushedBytes(int maxUnflushedBytes)
+    {
+        _maxUnflushedBytes = maxUnflushedBytes;
+    }
+
+    /**
+     * @return the number of bytes written since the last flush
+     */
+    public long getUnflushedBytes()
+    {
+        return _unflushed;
+    }
+
+    /**
+     * @return the number of times the wrapped stream was flushed
+     */
+    public long getFlushes()
+    {
+        return _flushes;
+    }
+
+    @Override
+    public void write(int b) throws IOException
+    {
+        write(new byte[]{(byte)b}, 0, 1);
+    }
+
+    @Override
+    public void write(byte[] b, int off, int len) throws IOException
+    {
+        Objects.checkFromIndexSize(off, len, b.length);
+        while (len > 0)
+        {
+            int chunk = len;
+            if (_maxUnflushedBytes > 0)
+                chunk = (int)Math.min(chunk, Math.max(1, _maxUnflushedBytes - _unflushed));
+
+            int end = _boundary.find(b, off, chunk);
+            boolean boundary = end >= 0;
+            if (boundary)
+                chunk = end - off;
+
+            out.write(b, off, chunk);
+            _unflushed += chunk;
+            off += chunk;
+            len -= chunk;
+
+            if (boundary ? isFlushDue() : _maxUnflushedBytes > 0 && _unflushed >= _maxUnflushedBytes)
+                flush();
+        }
+    }
+
+    private boolean isFlushDue()
+    {
+        if (_unflushed == 0)
+            return false;
+        if (_unflushed >= _flushBytes)
+            return true;
+        if (_maxUnflushedBytes > 0 && _unflushed >= _maxUnflushedBytes)
+            return true;
+        return _flushDelay >= 0 && NanoTime.since(_lastFlush) >= _flushDelay;
+    }
+
+    @Override
+    public void flush() throws IOException
+    {
+        out.flush();
+        _unflushed = 0;
+        _lastFlush = NanoTime.now();
+        ++_flushes;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,unflushed=%d]", getClass().getSimpleName(), hashCode(), _boundary, _unflushed);
+    }
+
+    /**
+     * <p>A stateful detector of content boundaries, fed with all the bytes written to the stream.</p>
+     */
+    public interface Boundary
+    {
+        /**
+         * <p>Scans the given bytes, updating the state of this detector, until the first boundary.</p>
+         *
+         * @param bytes the bytes to scan
+         * @param offset the offset of the first byte to scan
+         * @param length the number of bytes to scan
+         * @return the index just after the first boundary found, in which case the bytes after
+         * the returned index have not been scanned yet, or -1 if no boundary was found
+         */
+        int find(byte[] bytes, int offset, int length);
+
+        /**
+         * @return a boundary after each line feed
+         */
+        static Boundary lines()
+        {
+            return new Boundary()
+            {
+                @Override
+                public int find(byte[] bytes, int offset, int length)
+                {
+                    for (int i = offset; i < offset + length; ++i)
+                    {
+                        if (bytes[i] == '\n')
+                            return i + 1;
+                    }
+                    return -1;
+                }
+
+                @Override
+                public String toString()
+                {
+                    return "lines";
+                }
+            };
+        }
+
+        /**
+         * @param depth the maximum nesting depth of the values whose end is a boundary,
+         * for example 1 for the elements of a top level array
+         * @return a boundary after JSON values that complete at the given depth or less
+         * @see JsonBoundary
+         */
+        static Boundary json(int depth)
+        {
+            return new JsonBoundary(depth);
+        }
+
+        /**
+         * @return a boundary after every HTML end tag, comment or doctype
+         * @see HtmlBoundary
+         */
+        static Boundary html()
+        {
+            return new HtmlBoundary();
+        }
+    }
+
+    /**
+     * <p>Detects the end of JSON values nested at most at a given depth.</p>
+     * <p>A boundary is found after a value separator {@code ,} or after the closing
+     * brace or bracket of an object or array, when the depth after them is at most the
+     * configured depth, and after a line feed outside any structure, so that
+     * newline-delimited JSON streams are also supported.</p>
+     */
+    public static class JsonBoundary implements Boundary
+    {
+        private final int _maxDepth;
+        private int _depth;
+        private boolean _string;
+        private boolean _escape;
+
+        public JsonBoundary(int depth)
+        {
+            _maxDepth = depth;
+        }
+
+        @Override
+        public int find(byte[] bytes, int offset, int length)
+        {
+            for (int i = offset; i < offset + length; ++i)
+            {
+                byte b = bytes[i];
+                if (_string)
+                {
+                    if (_escape)
+                        _escape = false;
+                    else if (b == '\\')
+                        _escape = true;
+                    else if (b == '"')
+                        _string = false;
+                    continue;
+                }
+
+                switch (b)
+                {
+                    case '"':
+                        _string = true;
+                        break;
+                    case '{':
+                    case '[':
+                        ++_depth;
+                        break;
+                    case '}':
+                    case ']':
+                        if (_depth > 0)
+                            --_depth;
+                        if (_depth <= _maxDepth)
+                            return i + 1;
+                        break;
+                    case ',':
+                        if (_depth <= _maxDepth)
+                            return i + 1;
+                        break;
+                    case '\n':
+                        if (_depth == 0)
+                            return i + 1;
+                        break;
+                    default:
+                        break;
+                }
+            }
+            return -1;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("json[depth=%d]", _maxDepth);
+        }
+    }
+
+    /**
+     * <p>Detects the end of HTML elements.</p>
+     * <p>A boundary is found after the {@code >} of an end tag such as {@code </div>},
+     * of a comment or of a doctype declaration. Quoted attribute values are skipped,
+     * so that a {@code >} in an attribute value is not mistaken for the end of a tag.</p>
+     */
+    public static class HtmlBoundary implements Boundary
+    {
+        private State _state = State.TEXT;
+        private boolean _endTag;
+        private byte _quote;
+        private int _dashes;
+
+        @Override
+        public int find(byte[] bytes, int offset, int length)
+        {
+            for (int i = offset; i < offset + length; ++i)
+            {
+                byte b = bytes[i];
+                switch (_state)
+                {
+                    case TEXT:
+                        if (b == '<')
+                            _state = State.TAG_OPEN;
+                        break;
+                    case TAG_OPEN:
+                        if (b == '!')
+                        {
+                            _state = State.DECLARATION;
+                            _dashes = 0;
+                        }
+                        else
+                        {
+                            _endTag = b == '/';
+                            _state = b == '<' ? State.TAG_OPEN : State.TAG;
+                            if (b == '>')
+                                _state = State.TEXT;
+                        }
+                        break;
+                    case DECLARATION:
+                        // Either "<!--" to start a comment, or "<!DOCTYPE ...>".
+                        if (b == '-' && ++_dashes == 2)
+                        {
+                            _state = State.COMMENT;
+                            _dashes = 0;
+                        }
+                        else if (b == '>')
+                        {
+                            _state = State.TEXT;
+                            return i + 1;
+                        }
+                        else if (b != '-')
+                        {
+                            _endTag = true;
+                            _state = State.TAG;
+                        }
+                        break;
+                    case COMMENT:
+                        if (b == '-')
+                        {
+                            ++_dashes;
+                        }
+                        else
+                        {
+                            boolean end = b == '>' && _dashes >= 2;
+                            _dashes = 0;
+                            if (end)
+                            {
+                                _state = State.TEXT;
+                                return i + 1;
+                            }
+                        }
+                        break;
+                    case TAG:
+                        if (_quote != 0)
+                        {
+                            if (b == _quote)
+                                _quote = 0;
+                        }
+                        else if (b == '"' || b == '\'')
+                        {
+                            _quote = b;
+                        }
+                        else if (b == '>')
+                        {
+                            _state = State.TEXT;
+                            if (_endTag)
+                                return i + 1;
+                        }
+                        break;
+                    default:
+                        throw new IllegalStateException(_state.toString());
+                }
+            }
+            return -1;
+        }
+
+        @Override
+        public String toString()
+        {
+            return "html";
+        }
+
+        private enum State
+        {
+            TEXT, TAG_OPEN, TAG, DECLARATION, COMMENT
+        }
+    }
+}
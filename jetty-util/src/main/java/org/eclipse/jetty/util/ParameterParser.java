@@ -0,0 +1,533 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.nio.ByteBuffer;
+import java.nio.CharBuffer;
+import java.nio.charset.CharacterCodingException;
+import java.nio.charset.Charset;
+import java.nio.charset.CharsetDecoder;
+import java.nio.charset.CodingErrorAction;
+import java.nio.charset.IllegalCharsetNameException;
+import java.nio.charset.StandardCharsets;
+import java.nio.charset.UnsupportedCharsetException;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.List;
+
+/**
+ * <p>A parser of {@code application/x-www-form-urlencoded} content,
+ * used for both URI query strings and form request content.</p>
+ * <p>A parser can be configured with limits to protect against
+ * denial of service attacks:</p>
+ * <ul>
+ * <li>{@link #setMaxKeys(int) max keys}, the max number of distinct keys</li>
+ * <li>{@link #setMaxValueLength(int) max value length}, the max length of a decoded value</li>
+ * <li>{@link #setMaxLength(int) max length}, the max number of content bytes read
+ * by {@link #parse(InputStream, Charset, MultiMap)}</li>
+ * </ul>
+ * <p>When no charset is specified, and a {@code _charset_} field is present, as
+ * defined by the HTML specification, its value is used as the charset to decode
+ * the keys and values, otherwise {@link UrlEncoded#ENCODING} is used.</p>
+ * <p>The {@link Decoding} mode specifies how invalid percent-encoded sequences
+ * and invalid byte sequences for the charset are treated.</p>
+ * <p>Limit violations and decoding errors are reported to the
+ * {@link ViolationListener}, if any, that decides whether the violation is
+ * tolerated; otherwise a {@link ViolationException} is thrown.
+ * Tolerated violations are handled as follows, so that the content
+ * is never silently truncated:</p>
+ * <ul>
+ * <li>{@link Violation#TOO_MANY_KEYS}: the remaining keys are ignored</li>
+ * <li>{@link Violation#VALUE_TOO_LONG}: the parameter is ignored</li>
+ * <li>{@link Violation#CONTENT_TOO_LARGE}: the content is parsed up to the max length,
+ * ignoring the last incomplete parameter</li>
+ * <li>{@link Violation#BAD_ENCODING}: the invalid sequence is decoded leniently</li>
+ * <li>{@link Violation#UNSUPPORTED_CHARSET}: the default charset is used</li>
+ * </ul>
+ * <p>Instances of this class are thread-safe once configured.</p>
+ */
+public class ParameterParser
+{
+    public static final String CHARSET_PARAMETER = "_charset_";
+    private static final String ASCII_CHECK = "%&=+";
+
+    private int _maxKeys = -1;
+    private int _maxValueLength = -1;
+    private int _maxLength = -1;
+    private boolean _charsetParameter = true;
+    private Decoding _decoding = Decoding.REPLACE;
+    private ViolationListener _violationListener;
+
+    /**
+     * @return the max number of distinct keys, or -1 for no limit
+     */
+    public int getMaxKeys()
+    {
+        return _maxKeys;
+    }
+
+    /**
+     * @param maxKeys the max number of distinct keys, or -1 for no limit
+     */
+    public void setMaxKeys(int maxKeys)
+    {
+        _maxKeys = maxKeys;
+    }
+
+    /**
+     * @return the max length of a decoded value, or -1 for no limit
+     */
+    public int getMaxValueLength()
+    {
+        return _maxValueLength;
+    }
+
+    /**
+     * @param maxValueLength the max length of a decoded value, or -1 for no limit
+     */
+    public void setMaxValueLength(int maxValueLength)
+    {
+        _maxValueLength = maxValueLength;
+    }
+
+    /**
+     * @return the max number of content bytes to read, or -1 for no limit
+     */
+    public int getMaxLength()
+    {
+        return _maxLength;
+    }
+
+    /**
+     * @param maxLength the max number of content bytes to read, or -1 for no limit
+     */
+    public void setMaxLength(int maxLength)
+    {
+        _maxLength = maxLength;
+    }
+
+    /**
+     * @return whether the {@code _charset_} field is used when no charset is specified
+     */
+    public boolean isCharsetParameter()
+    {
+        return _charsetParameter;
+    }
+
+    /**
+     * @param charsetParameter whether the {@code _charset_} field is used when no charset is specified
+     */
+    public void setCharsetParameter(boolean charsetParameter)
+    {
+        _charsetParameter = charsetParameter;
+    }
+
+    /**
+     * @return the decoding mode
+     */
+    public Decoding getDecoding()
+    {
+        return _decoding;
+    }
+
+    /**
+     * @param decoding the decoding mode
+     */
+    public void setDecoding(Decoding decoding)
+    {
+        _decoding = decoding == null ? Decoding.REPLACE : decoding;
+    }
+
+    /**
+     * @return the listener of violations, or null if violations are not tolerated
+     */
+    public ViolationListener getViolationListener()
+    {
+        return _violationListener;
+    }
+
+    /**
+     * @param violationListener the listener of violations, or null if violations are not tolerated
+     */
+    public void setViolationListener(ViolationListener violationListener)
+    {
+        _violationListener = violationListener;
+    }
+
+    /**
+     * <p>Parses the given string, typically a URI query string.</p>
+     *
+     * @param content the string to parse
+     * @param charset the charset of the percent-encoded bytes, or null to use
+     * the {@code _charset_} field or {@link UrlEncoded#ENCODING}
+     * @param map the map to add the parameters to
+     * @throws ViolationException if a violation is not tolerated
+     */
+    public void parse(String content, Charset charset, MultiMap<String> map)
+    {
+        if (content == null || content.isEmpty())
+            return;
+        parse(content, false, charset, map);
+    }
+
+    /**
+     * <p>Parses the given content, typically form request content.</p>
+     * <p>At most {@link #getMaxLength() max length} bytes are read.</p>
+     *
+     * @param input the content to parse
+     * @param charset the charset of the content, or null to use
+     * the {@code _charset_} field or {@link UrlEncoded#ENCODING}
+     * @param map the map to add the parameters to
+     * @throws IOException if the content cannot be read
+     * @throws ViolationException if a violation is not tolerated
+     */
+    public void parse(InputStream input, Charset charset, MultiMap<String> map) throws IOException
+    {
+        ByteArrayOutputStream2 bytes = new ByteArrayOutputStream2();
+        byte[] buffer = new byte[4096];
+        boolean truncated = false;
+        while (true)
+        {
+            int read = input.read(buffer);
+            if (read < 0)
+                break;
+            if (_maxLength >= 0 && bytes.size() + read > _maxLength)
+            {
+                violation(Violation.CONTENT_TOO_LARGE, "Form is larger than max length " + _maxLength);
+                bytes.write(buffer, 0, _maxLength - bytes.size());
+                truncated = true;
+                break;
+            }
+            bytes.write(buffer, 0, read);
+        }
+
+        String content;
+        boolean raw;
+        if (charset == null || isAsciiCompatible(charset))
+        {
+            // Every char represents one byte, decoded later with the actual charset.
+            content = bytes.toString(StandardCharsets.ISO_8859_1);
+            raw = true;
+        }
+        else
+        {
+            content = bytes.toString(charset);
+            raw = false;
+        }
+
+        if (truncated)
+        {
+            // Ignore the last, possibly incomplete, parameter.
+            int amp = content.lastIndexOf('&');
+            content = amp < 0 ? "" : content.substring(0, amp);
+        }
+
+        if (!content.isEmpty())
+            parse(content, raw, charset, map);
+    }
+
+    private void parse(String content, boolean raw, Charset charset, MultiMap<String> map)
+    {
+        List<int[]> fields = split(content);
+
+        if (charset == null)
+            charset = findCharset(content, fields);
+
+        for (int[] field : fields)
+        {
+            int start = field[0];
+            int equals = field[1];
+            int end = field[2];
+
+            String key = decode(content, start, equals < 0 ? end : equals, raw, charset);
+            String value = equals < 0 ? "" : decode(content, equals + 1, end, raw, charset);
+            if (key.isEmpty() && value.isEmpty())
+                continue;
+            if (equals < 0 && key.isEmpty())
+                continue;
+
+            if (_maxValueLength >= 0 && value.length() > _maxValueLength)
+            {
+                violation(Violation.VALUE_TOO_LONG, String.format("Form value for key %s is too long [%d > %d]", key, value.length(), _maxValueLength));
+                continue;
+            }
+
+            if (_maxKeys >= 0 && !map.containsKey(key) && map.size() >= _maxKeys)
+            {
+                violation(Violation.TOO_MANY_KEYS, String.format("Form with too many keys [%d > %d]", map.size() + 1, _maxKeys));
+                return;
+            }
+
+            map.add(key, value);
+        }
+    }
+
+    private static List<int[]> split(String content)
+    {
+        List<int[]> fields = new ArrayList<>();
+        int start = 0;
+        int equals = -1;
+        int length = content.length();
+        for (int i = 0; i <= length; ++i)
+        {
+            char c = i == length ? '&' : content.charAt(i);
+            if (c == '&')
+            {
+                if (i > start)
+                    fields.add(new int[]{start, equals, i});
+                start = i + 1;
+                equals = -1;
+            }
+            else if (c == '=' && equals < 0)
+            {
+                equals = i;
+            }
+        }
+        return fields;
+    }
+
+    private Charset findCharset(String content, List<int[]> fields)
+    {
+        if (_charsetParameter)
+        {
+            for (int[] field : fields)
+            {
+                int equals = field[1];
+                if (equals - field[0] != CHARSET_PARAMETER.length() || !content.startsWith(CHARSET_PARAMETER, field[0]))
+                    continue;
+                String name = content.substring(equals + 1, field[2]).trim();
+                try
+                {
+                    return Charset.forName(name);
+                }
+                catch (IllegalCharsetNameException | UnsupportedCharsetException x)
+                {
+                    violation(Violation.UNSUPPORTED_CHARSET, "Unsupported form charset " + name);
+                    break;
+                }
+            }
+        }
+        return UrlEncoded.ENCODING;
+    }
+
+    private String decode(String content, int start, int end, boolean raw, Charset charset)
+    {
+        if (!needsDecoding(content, start, end, raw))
+            return content.substring(start, end);
+
+        StringBuilder result = new StringBuilder(end - start);
+        ByteArrayOutputStream2 bytes = new ByteArrayOutputStream2(end - start);
+        for (int i = start; i < end; ++i)
+        {
+            char c = content.charAt(i);
+            if (c == '+')
+            {
+                bytes.write(' ');
+            }
+            else if (c == '%')
+            {
+                int hi = i + 1 < end ? hexValue(content.charAt(i + 1)) : -1;
+                int lo = i + 2 < end ? hexValue(content.charAt(i + 2)) : -1;
+                if (hi >= 0 && lo >= 0)
+                {
+                    bytes.write((hi << 4) + lo);
+                    i += 2;
+                }
+                else
+                {
+                    if (_decoding != Decoding.LENIENT)
+                        violation(Violation.BAD_ENCODING, "Invalid percent-encoding in form");
+                    bytes.write('%');
+                }
+            }
+            else if (raw || c < 0x80)
+            {
+                bytes.write(c);
+            }
+            else
+            {
+                flush(bytes, charset, result);
+                result.append(c);
+            }
+        }
+        flush(bytes, charset, result);
+        return result.toString();
+    }
+
+    private static int hexValue(char c)
+    {
+        if (c >= '0' && c <= '9')
+            return c - '0';
+        if (c >= 'a' && c <= 'f')
+            return c - 'a' + 10;
+        if (c >= 'A' && c <= 'F')
+            return c - 'A' + 10;
+        return -1;
+    }
+
+    private static boolean needsDecoding(String content, int start, int end, boolean raw)
+    {
+        for (int i = start; i < end; ++i)
+        {
+            char c = content.charAt(i);
+            if (c == '+' || c == '%' || (raw && c >= 0x80))
+                return true;
+        }
+        return false;
+    }
+
+    private void flush(ByteArrayOutputStream2 bytes, Charset charset, StringBuilder result)
+    {
+        if (bytes.size() == 0)
+            return;
+        ByteBuffer buffer = ByteBuffer.wrap(bytes.getBuf(), 0, bytes.size());
+        if (_decoding == Decoding.STRICT)
+        {
+            try
+            {
+                CharsetDecoder decoder = charset.newDecoder()
+                    .onMalformedInput(CodingErrorAction.REPORT)
+                    .onUnmappableCharacter(CodingErrorAction.REPORT);
+                CharBuffer chars = decoder.decode(buffer);
+                result.append(chars);
+                bytes.reset();
+                return;
+            }
+            catch (CharacterCodingException x)
+            {
+                violation(Violation.BAD_ENCODING, "Invalid " + charset.name() + " sequence in form");
+                buffer.rewind();
+            }
+        }
+        result.append(charset.decode(buffer));
+        bytes.reset();
+    }
+
+    private void violation(Violation violation, String message)
+    {
+        ViolationListener listener = _violationListener;
+        if (listener == null || !listener.onViolation(violation, message))
+            throw new ViolationException(violation, message);
+    }
+
+    private static boolean isAsciiCompatible(Charset charset)
+    {
+        if (StandardCharsets.UTF_8.equals(charset) || StandardCharsets.ISO_8859_1.equals(charset) || StandardCharsets.US_ASCII.equals(charset))
+            return true;
+        try
+        {
+            return charset.canEncode() && Arrays.equals(ASCII_CHECK.getBytes(charset), ASCII_CHECK.getBytes(StandardCharsets.US_ASCII));
+        }
+        catch (UnsupportedOperationException x)
+        {
+            return false;
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[keys=%d,value=%d,length=%d,decoding=%s]", getClass().getSimpleName(), hashCode(), _maxKeys, _maxValueLength, _maxLength, _decoding);
+    }
+
+    /**
+     * <p>The modes to decode percent-encoded sequences and charset byte sequences.</p>
+     */
+    public enum Decoding
+    {
+        /**
+         * <p>Invalid percent-encoded sequences and invalid byte sequences
+         * for the charset are {@link Violation#BAD_ENCODING violations}.</p>
+         */
+        STRICT,
+        /**
+         * <p>Invalid percent-encoded sequences are {@link Violation#BAD_ENCODING violations},
+         * while invalid byte sequences for the charset are replaced with the replacement character.</p>
+         */
+        REPLACE,
+        /**
+         * <p>Invalid percent-encoded sequences are retained literally, while invalid
+         * byte sequences for the charset are replaced with the replacement character.</p>
+         */
+        LENIENT
+    }
+
+    /**
+     * <p>The kinds of violations detected while parsing.</p>
+     */
+    public enum Violation
+    {
+        /**
+         * <p>The number of distinct keys exceeds the max keys.</p>
+         */
+        TOO_MANY_KEYS,
+        /**
+         * <p>The length of a value exceeds the max value length.</p>
+         */
+        VALUE_TOO_LONG,
+        /**
+         * <p>The content length exceeds the max length.</p>
+         */
+        CONTENT_TOO_LARGE,
+        /**
+         * <p>An invalid percent-encoded sequence or byte sequence for the charset.</p>
+         */
+        BAD_ENCODING,
+        /**
+         * <p>The {@code _charset_} field specifies an unsupported charset.</p>
+         */
+        UNSUPPORTED_CHARSET
+    }
+
+    /**
+     * <p>A listener of parsing violations.</p>
+     */
+    @FunctionalInterface
+    public interface ViolationListener
+    {
+        /**
+         * <p>Callback method invoked when a violation is detected.</p>
+         *
+         * @param violation the violation
+         * @param message the violation message
+         * @return true to tolerate the violation and continue parsing,
+         * false to fail parsing with a {@link ViolationException}
+         */
+        boolean onViolation(Violation violation, String message);
+    }
+
+    /**
+     * <p>The exception thrown when a violation is not tolerated.</p>
+     */
+    public static class ViolationException extends IllegalStateException
+    {
+        private final Violation _violation;
+
+        public ViolationException(Violation violation, String message)
+        {
+            super(message);
+            _violation = violation;
+        }
+
+        /**
+         * @return the violation
+         */
+        public Violation getViolation()
+        {
+            return _violation;
+        }
+    }
+}
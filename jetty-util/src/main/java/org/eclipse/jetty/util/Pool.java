@@ -62,6 +62,7 @@ public class Pool<T> implements AutoCloseable, Dumpable
     private final AutoLock lock = new AutoLock();
     private final ThreadLocal<Entry> cache;
     private final AtomicInteger nextIndex;
+    private final Stripes stripes;
     private volatile boolean closed;
     @Deprecated
     private volatile int maxUsage = -1;
@@ -101,7 +102,18 @@ public class Pool<T> implements AutoCloseable, Dumpable
          * random strategy but with more predictable behaviour.
          * No entries are favoured and contention is reduced.
          */
-        ROUND_ROBIN
+        ROUND_ROBIN,
+
+        /**
+         * A strategy that divides the entries into stripes, one per available processor,
+         * and assigns each thread a home stripe that it searches first.
+         * When the home stripe has no available entry, the thread steals an entry from
+         * the other stripes, and a thread that keeps stealing from another stripe moves
+         * its home there, so that stripes rebalance when the load is uneven.
+         * Contention is reduced on machines with many cores, at the cost of a
+         * {@link ThreadLocal} lookup for every search.
+         */
+        STRIPED
     }
 
     /**
@@ -130,6 +142,7 @@ public class Pool<T> implements AutoCloseable, Dumpable
         this.strategyType = Objects.requireNonNull(strategyType);
         this.cache = cache ? new ThreadLocal<>() : null;
         this.nextIndex = strategyType == StrategyType.ROUND_ROBIN ? new AtomicInteger() : null;
+        this.stripes = strategyType == StrategyType.STRIPED ? new Stripes(ProcessorUtils.availableProcessors()) : null;
     }
 
     /**
@@ -392,6 +405,9 @@ public class Pool<T> implements AutoCloseable, Dumpable
                 return entry;
         }
 
+        if (stripes != null)
+            return stripes.acquire(size);
+
         int index = startIndex(size);
 
         for (int tries = size; tries-- > 0;)
@@ -434,6 +450,107 @@ public class Pool<T> implements AutoCloseable, Dumpable
         }
     }
 
+    /**
+     * <p>The state of the {@link StrategyType#STRIPED} strategy.</p>
+     * <p>Stripes are computed from the current size of the pool, so that
+     * entries that are added or removed are spread over all the stripes.</p>
+     */
+    private class Stripes
+    {
+        // The number of consecutive steals from another stripe after which a thread moves its home stripe.
+        private static final int REBALANCE_THRESHOLD = 8;
+
+        private final int count;
+        private final ThreadLocal<Home> homes;
+
+        private Stripes(int count)
+        {
+            this.count = Math.max(1, count);
+            this.homes = ThreadLocal.withInitial(() -> new Home((int)(Thread.currentThread().getId() % this.count)));
+        }
+
+        private Entry acquire(int size)
+        {
+            int stripeCount = Math.min(count, size);
+            int stripeSize = (size + stripeCount - 1) / stripeCount;
+            // Rounding up the stripe size may leave the last stripes empty.
+            stripeCount = (size + stripeSize - 1) / stripeSize;
+            Home home = homes.get();
+            int homeStripe = home.stripe % stripeCount;
+
+            Entry entry = acquire(homeStripe, stripeSize, size, 0);
+            if (entry != null)
+            {
+                home.steals = 0;
+                return entry;
+            }
+
+            // Steal from the other stripes, starting from a random one.
+            int start = ThreadLocalRandom.current().nextInt(stripeCount);
+            for (int i = 0; i < stripeCount; i++)
+            {
+                int stripe = (start + i) % stripeCount;
+                if (stripe == homeStripe)
+                    continue;
+                entry = acquire(stripe, stripeSize, size, ThreadLocalRandom.current().nextInt(stripeSize));
+                if (entry != null)
+                {
+                    if (stripe == home.victim)
+                    {
+                        if (++home.steals >= REBALANCE_THRESHOLD)
+                        {
+                            if (LOGGER.isDebugEnabled())
+                                LOGGER.debug("Moving home stripe of {} from {} to {} in {}", Thread.currentThread(), homeStripe, stripe, Pool.this);
+                            home.stripe = stripe;
+                            home.steals = 0;
+                        }
+                    }
+                    else
+                    {
+                        home.victim = stripe;
+                        home.steals = 1;
+                    }
+                    return entry;
+                }
+            }
+            return null;
+        }
+
+        private Entry acquire(int stripe, int stripeSize, int size, int offset)
+        {
+            int first = stripe * stripeSize;
+            int length = Math.min(first + stripeSize, size) - first;
+            for (int i = 0; i < length; i++)
+            {
+                try
+                {
+                    Pool<T>.Entry entry = entries.get(first + (offset + i) % length);
+                    if (entry != null && entry.tryAcquire())
+                        return entry;
+                }
+                catch (IndexOutOfBoundsException e)
+                {
+                    // The pool shrank, move on to the next stripe.
+                    LOGGER.trace("IGNORED", e);
+                    return null;
+                }
+            }
+            return null;
+        }
+    }
+
+    private static class Home
+    {
+        private int stripe;
+        private int victim = -1;
+        private int steals;
+
+        private Home(int stripe)
+        {
+            this.stripe = stripe;
+        }
+    }
+
     /**
      * <p>Acquires an entry from the pool,
      * reserving and creating a new entry if necessary.</p>
@@ -471,6 +588,21 @@ public class Pool<T> implements AutoCloseable, Dumpable
         return entry.enable(value, true) ? entry : null;
     }
 
+    /**
+     * <p>Acquires the given entry, typically selected among the {@link #values() entries}
+     * of this pool with a strategy other than the {@link StrategyType} of this pool.</p>
+     *
+     * @param entry the entry to acquire
+     * @return true if the entry was acquired, false if this pool is closed, or the entry is
+     * reserved, closed, or it cannot be acquired more times concurrently or in total
+     */
+    public boolean acquire(Entry entry)
+    {
+        if (closed)
+            return false;
+        return entry.tryAcquire();
+    }
+
     /**
      * <p>Releases an {@link #acquire() acquired} entry to the pool.</p>
      * <p>Entries that are acquired from the pool but never released
@@ -706,6 +838,15 @@ public class Pool<T> implements AutoCloseable, Dumpable
          */
         public abstract boolean isInUse();
 
+        /**
+         * @return the number of times this entry is currently acquired,
+         * at most 1 if this entry cannot be acquired concurrently
+         */
+        public int getMultiplexCount()
+        {
+            return isInUse() ? 1 : 0;
+        }
+
         /**
          * @return whether this entry has been used beyond {@link #getMaxUsageCount()}
          * @deprecated MaxUsage functionalities will be removed
@@ -996,6 +1137,13 @@ public class Pool<T> implements AutoCloseable, Dumpable
             return maxUsageCount > 0 && usageCount >= maxUsageCount && multiplexCount == 0;
         }
 
+        @Override
+        public int getMultiplexCount()
+        {
+            long encoded = state.get();
+            return AtomicBiInteger.getHi(encoded) < 0 ? 0 : AtomicBiInteger.getLo(encoded);
+        }
+
         @Override
         int getUsageCount()
         {
@@ -0,0 +1,33 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.component;
+
+/**
+ * <p>A Validatable is a component that can check its configuration without being started.</p>
+ * <p>Typically a Validatable acquires the resources it needs when started, such as ports,
+ * keystores or database connections, and releases them before returning from
+ * {@link #validate(ValidationReport)}, so that a configuration can be verified
+ * in a CI pipeline or during a config review without serving traffic.</p>
+ */
+public interface Validatable
+{
+    /**
+     * <p>Checks the configuration of this component.</p>
+     * <p>All the problems found are added to the given report, rather than
+     * failing at the first problem.</p>
+     *
+     * @param report the report to add the problems to
+     */
+    void validate(ValidationReport report);
+}
@@ -0,0 +1,165 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.component;
+
+import java.util.ArrayList;
+import java.util.List;
+
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>The problems found by {@link Validatable#validate(ValidationReport) validating} components.</p>
+ */
+public class ValidationReport
+{
+    private final AutoLock _lock = new AutoLock();
+    private final List<Problem> _problems = new ArrayList<>();
+
+    /**
+     * <p>Validates the given component, recording a problem if the validation throws.</p>
+     *
+     * @param validatable the component to validate
+     */
+    public void validate(Validatable validatable)
+    {
+        try
+        {
+            validatable.validate(this);
+        }
+        catch (Throwable x)
+        {
+            addProblem(validatable, x);
+        }
+    }
+
+    /**
+     * @param source the component with the problem
+     * @param message the problem description
+     */
+    public void addProblem(Object source, String message)
+    {
+        addProblem(new Problem(source, message, null));
+    }
+
+    /**
+     * @param source the component with the problem
+     * @param cause the failure that describes the problem
+     */
+    public void addProblem(Object source, Throwable cause)
+    {
+        addProblem(new Problem(source, String.valueOf(cause), cause));
+    }
+
+    /**
+     * @param source the component with the problem
+     * @param message the problem description
+     * @param cause the failure that caused the problem
+     */
+    public void addProblem(Object source, String message, Throwable cause)
+    {
+        addProblem(new Problem(source, message, cause));
+    }
+
+    private void addProblem(Problem problem)
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            _problems.add(problem);
+        }
+    }
+
+    /**
+     * @return the problems found, in the order they have been added
+     */
+    public List<Problem> getProblems()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return List.copyOf(_problems);
+        }
+    }
+
+    /**
+     * @return whether no problem has been found
+     */
+    public boolean isValid()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _problems.isEmpty();
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        List<Problem> problems = getProblems();
+        if (problems.isEmpty())
+            return "Validation passed";
+        StringBuilder builder = new StringBuilder();
+        builder.append("Validation failed with ").append(problems.size()).append(" problem(s):");
+        for (Problem problem : problems)
+        {
+            builder.append(System.lineSeparator()).append("  ").append(problem);
+        }
+        return builder.toString();
+    }
+
+    /**
+     * <p>A problem found while validating a component.</p>
+     */
+    public static class Problem
+    {
+        private final Object _source;
+        private final String _message;
+        private final Throwable _cause;
+
+        public Problem(Object source, String message, Throwable cause)
+        {
+            _source = source;
+            _message = message;
+            _cause = cause;
+        }
+
+        /**
+         * @return the component with the problem
+         */
+        public Object getSource()
+        {
+            return _source;
+        }
+
+        /**
+         * @return the problem description
+         */
+        public String getMessage()
+        {
+            return _message;
+        }
+
+        /**
+         * @return the failure that caused the problem, or {@code null}
+         */
+        public Throwable getCause()
+        {
+            return _cause;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s: %s", _source, _message);
+        }
+    }
+}
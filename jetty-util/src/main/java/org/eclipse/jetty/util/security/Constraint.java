@@ -38,6 +38,7 @@ public class Constraint implements Cloneable, Serializable
 
     public static final String __NEGOTIATE_AUTH = "NEGOTIATE";
     public static final String __OPENID_AUTH = "OPENID";
+    public static final String __JWT_AUTH = "JWT";
 
     public static boolean validateMethod(String method)
     {
@@ -51,7 +52,8 @@ public class Constraint implements Cloneable, Serializable
             method.equals(__CERT_AUTH2) ||
             method.equals(__SPNEGO_AUTH) ||
             method.equals(__NEGOTIATE_AUTH) ||
-            method.equals(__OPENID_AUTH));
+            method.equals(__OPENID_AUTH) ||
+            method.equals(__JWT_AUTH));
     }
 
     public static final int DC_UNSET = -1;
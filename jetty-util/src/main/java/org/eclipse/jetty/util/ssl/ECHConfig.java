@@ -0,0 +1,327 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.ByteArrayOutputStream;
+import java.nio.BufferUnderflowException;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.List;
+import java.util.Objects;
+
+/**
+ * <p>A TLS Encrypted Client Hello configuration, the {@code ECHConfig} structure
+ * defined by the TLS Encrypted Client Hello specification.</p>
+ * <p>Servers publish their configurations in the {@code ech} parameter of their DNS
+ * {@code HTTPS} records, encoded as an {@code ECHConfigList}; clients use a configuration
+ * to encrypt the inner ClientHello with {@link HPKE}, while the outer ClientHello carries
+ * the {@link #getPublicName() public name} of the configuration as SNI.</p>
+ * <p>Only the configurations with version {@link #VERSION} are supported: configurations
+ * with other versions are skipped by {@link #parseList(byte[])}.</p>
+ */
+public class ECHConfig
+{
+    public static final int VERSION = 0xFE0D;
+
+    private final int _configId;
+    private final int _kemId;
+    private final byte[] _publicKey;
+    private final List<CipherSuite> _cipherSuites;
+    private final int _maxNameLength;
+    private final String _publicName;
+    private final byte[] _extensions;
+
+    public ECHConfig(int configId, int kemId, byte[] publicKey, List<CipherSuite> cipherSuites, int maxNameLength, String publicName)
+    {
+        this(configId, kemId, publicKey, cipherSuites, maxNameLength, publicName, new byte[0]);
+    }
+
+    private ECHConfig(int configId, int kemId, byte[] publicKey, List<CipherSuite> cipherSuites, int maxNameLength, String publicName, byte[] extensions)
+    {
+        if (configId < 0 || configId > 255)
+            throw new IllegalArgumentException("Invalid config id " + configId);
+        if (cipherSuites.isEmpty())
+            throw new IllegalArgumentException("No cipher suites");
+        _configId = configId;
+        _kemId = kemId;
+        _publicKey = publicKey.clone();
+        _cipherSuites = List.copyOf(cipherSuites);
+        _maxNameLength = maxNameLength;
+        _publicName = Objects.requireNonNull(publicName);
+        _extensions = extensions;
+    }
+
+    /**
+     * <p>Parses an {@code ECHConfigList}, skipping the configurations with unsupported versions.</p>
+     *
+     * @param bytes the encoded {@code ECHConfigList}, including its 2 bytes length prefix
+     * @return the configurations with a supported version
+     * @throws IllegalArgumentException if the bytes are not a valid {@code ECHConfigList}
+     */
+    public static List<ECHConfig> parseList(byte[] bytes)
+    {
+        try
+        {
+            ByteBuffer buffer = ByteBuffer.wrap(bytes);
+            ByteBuffer list = slice(buffer, buffer.getShort() & 0xFFFF);
+            if (buffer.hasRemaining())
+                throw new IllegalArgumentException("Invalid ECHConfigList length");
+            List<ECHConfig> result = new ArrayList<>();
+            while (list.hasRemaining())
+            {
+                int version = list.getShort() & 0xFFFF;
+                ByteBuffer contents = slice(list, list.getShort() & 0xFFFF);
+                if (version == VERSION)
+                    result.add(parseContents(contents));
+            }
+            return result;
+        }
+        catch (BufferUnderflowException | IndexOutOfBoundsException x)
+        {
+            throw new IllegalArgumentException("Invalid ECHConfigList", x);
+        }
+    }
+
+    private static ECHConfig parseContents(ByteBuffer contents)
+    {
+        int configId = contents.get() & 0xFF;
+        int kemId = contents.getShort() & 0xFFFF;
+        byte[] publicKey = toArray(slice(contents, contents.getShort() & 0xFFFF));
+        ByteBuffer suites = slice(contents, contents.getShort() & 0xFFFF);
+        List<CipherSuite> cipherSuites = new ArrayList<>();
+        while (suites.hasRemaining())
+        {
+            cipherSuites.add(new CipherSuite(suites.getShort() & 0xFFFF, suites.getShort() & 0xFFFF));
+        }
+        int maxNameLength = contents.get() & 0xFF;
+        String publicName = new String(toArray(slice(contents, contents.get() & 0xFF)), StandardCharsets.US_ASCII);
+        byte[] extensions = toArray(slice(contents, contents.getShort() & 0xFFFF));
+        if (contents.hasRemaining())
+            throw new IllegalArgumentException("Invalid ECHConfig length");
+        return new ECHConfig(configId, kemId, publicKey, cipherSuites, maxNameLength, publicName, extensions);
+    }
+
+    private static ByteBuffer slice(ByteBuffer buffer, int length)
+    {
+        if (length > buffer.remaining())
+            throw new BufferUnderflowException();
+        ByteBuffer result = buffer.slice();
+        result.limit(length);
+        buffer.position(buffer.position() + length);
+        return result;
+    }
+
+    private static byte[] toArray(ByteBuffer buffer)
+    {
+        byte[] result = new byte[buffer.remaining()];
+        buffer.get(result);
+        return result;
+    }
+
+    /**
+     * @param configs the configurations
+     * @return the encoded {@code ECHConfigList}, including its 2 bytes length prefix
+     */
+    public static byte[] encodeList(List<ECHConfig> configs)
+    {
+        ByteArrayOutputStream list = new ByteArrayOutputStream();
+        for (ECHConfig config : configs)
+        {
+            list.writeBytes(config.getEncoded());
+        }
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        writeShort(output, list.size());
+        output.writeBytes(list.toByteArray());
+        return output.toByteArray();
+    }
+
+    private static void writeShort(ByteArrayOutputStream output, int value)
+    {
+        output.write(value >>> 8);
+        output.write(value);
+    }
+
+    public int getConfigId()
+    {
+        return _configId;
+    }
+
+    public int getKemId()
+    {
+        return _kemId;
+    }
+
+    /**
+     * @return the encoded HPKE public key
+     */
+    public byte[] getPublicKey()
+    {
+        return _publicKey.clone();
+    }
+
+    public List<CipherSuite> getCipherSuites()
+    {
+        return _cipherSuites;
+    }
+
+    public int getMaxNameLength()
+    {
+        return _maxNameLength;
+    }
+
+    /**
+     * @return the host name sent as SNI in the outer ClientHello
+     */
+    public String getPublicName()
+    {
+        return _publicName;
+    }
+
+    /**
+     * @return whether the key encapsulation mechanism and at least one cipher suite are supported by {@link HPKE}
+     */
+    public boolean isSupported()
+    {
+        return _cipherSuites.stream().anyMatch(suite -> HPKE.isSupported(_kemId, suite.getKdfId(), suite.getAeadId()));
+    }
+
+    /**
+     * <p>Sets up the HPKE context that a client uses to encrypt the inner ClientHello.</p>
+     *
+     * @param cipherSuite the cipher suite to use, one of {@link #getCipherSuites()}
+     * @return the HPKE sender context
+     * @throws GeneralSecurityException if the context cannot be set up
+     */
+    public HPKE.Sender newSenderContext(CipherSuite cipherSuite) throws GeneralSecurityException
+    {
+        if (!_cipherSuites.contains(cipherSuite))
+            throw new GeneralSecurityException("Cipher suite " + cipherSuite + " not in " + this);
+        return HPKE.setupBaseS(cipherSuite.getKdfId(), cipherSuite.getAeadId(), _publicKey, getHPKEInfo());
+    }
+
+    /**
+     * @return the HPKE {@code info} parameter, the string {@code "tls ech"},
+     * a zero byte and the {@link #getEncoded() encoded} configuration
+     */
+    byte[] getHPKEInfo()
+    {
+        byte[] label = "tls ech".getBytes(StandardCharsets.US_ASCII);
+        byte[] encoded = getEncoded();
+        byte[] result = new byte[label.length + 1 + encoded.length];
+        System.arraycopy(label, 0, result, 0, label.length);
+        System.arraycopy(encoded, 0, result, label.length + 1, encoded.length);
+        return result;
+    }
+
+    /**
+     * @return the encoded {@code ECHConfig}, including the version and the length
+     */
+    public byte[] getEncoded()
+    {
+        ByteArrayOutputStream contents = new ByteArrayOutputStream();
+        contents.write(_configId);
+        writeShort(contents, _kemId);
+        writeShort(contents, _publicKey.length);
+        contents.writeBytes(_publicKey);
+        writeShort(contents, _cipherSuites.size() * 4);
+        for (CipherSuite suite : _cipherSuites)
+        {
+            writeShort(contents, suite.getKdfId());
+            writeShort(contents, suite.getAeadId());
+        }
+        contents.write(_maxNameLength);
+        byte[] publicName = _publicName.getBytes(StandardCharsets.US_ASCII);
+        contents.write(publicName.length);
+        contents.writeBytes(publicName);
+        writeShort(contents, _extensions.length);
+        contents.writeBytes(_extensions);
+
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        writeShort(output, VERSION);
+        writeShort(output, contents.size());
+        output.writeBytes(contents.toByteArray());
+        return output.toByteArray();
+    }
+
+    @Override
+    public boolean equals(Object obj)
+    {
+        if (this == obj)
+            return true;
+        if (!(obj instanceof ECHConfig))
+            return false;
+        return Arrays.equals(getEncoded(), ((ECHConfig)obj).getEncoded());
+    }
+
+    @Override
+    public int hashCode()
+    {
+        return Arrays.hashCode(getEncoded());
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{id=%d,kem=0x%04x,suites=%s,name=%s}", getClass().getSimpleName(), hashCode(), _configId, _kemId, _cipherSuites, _publicName);
+    }
+
+    /**
+     * <p>A HPKE cipher suite, made of a key derivation function and an AEAD algorithm.</p>
+     */
+    public static class CipherSuite
+    {
+        private final int _kdfId;
+        private final int _aeadId;
+
+        public CipherSuite(int kdfId, int aeadId)
+        {
+            _kdfId = kdfId;
+            _aeadId = aeadId;
+        }
+
+        public int getKdfId()
+        {
+            return _kdfId;
+        }
+
+        public int getAeadId()
+        {
+            return _aeadId;
+        }
+
+        @Override
+        public boolean equals(Object obj)
+        {
+            if (!(obj instanceof CipherSuite))
+                return false;
+            CipherSuite that = (CipherSuite)obj;
+            return _kdfId == that._kdfId && _aeadId == that._aeadId;
+        }
+
+        @Override
+        public int hashCode()
+        {
+            return Objects.hash(_kdfId, _aeadId);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("0x%04x/0x%04x", _kdfId, _aeadId);
+        }
+    }
+}
@@ -0,0 +1,382 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.ByteArrayOutputStream;
+import java.math.BigInteger;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.KeyFactory;
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.PrivateKey;
+import java.security.PublicKey;
+import java.security.interfaces.XECPublicKey;
+import java.security.spec.AlgorithmParameterSpec;
+import java.security.spec.NamedParameterSpec;
+import java.security.spec.XECPublicKeySpec;
+import javax.crypto.Cipher;
+import javax.crypto.KeyAgreement;
+import javax.crypto.Mac;
+import javax.crypto.spec.GCMParameterSpec;
+import javax.crypto.spec.IvParameterSpec;
+import javax.crypto.spec.SecretKeySpec;
+
+/**
+ * <p>Hybrid Public Key Encryption, as defined by RFC 9180, in base mode.</p>
+ * <p>Only the {@link #KEM_X25519_HKDF_SHA256 DHKEM(X25519, HKDF-SHA256)} key encapsulation
+ * mechanism is supported, with the HKDF-SHA256, HKDF-SHA384 and HKDF-SHA512 key derivation
+ * functions and the AES-128-GCM, AES-256-GCM and ChaCha20Poly1305 AEAD algorithms,
+ * which covers the cipher suites used by TLS Encrypted Client Hello.</p>
+ * <p>The sender calls {@link #setupBaseS(int, int, byte[], byte[])} with the public key
+ * of the recipient and transmits the {@link Sender#getEncapsulatedKey() encapsulated key}
+ * along with the ciphertexts; the recipient calls
+ * {@link #setupBaseR(int, int, PrivateKey, byte[], byte[])} with its private key and the
+ * encapsulated key to obtain the context that decrypts the ciphertexts.</p>
+ */
+public class HPKE
+{
+    public static final int KEM_X25519_HKDF_SHA256 = 0x0020;
+    public static final int KDF_HKDF_SHA256 = 0x0001;
+    public static final int KDF_HKDF_SHA384 = 0x0002;
+    public static final int KDF_HKDF_SHA512 = 0x0003;
+    public static final int AEAD_AES_128_GCM = 0x0001;
+    public static final int AEAD_AES_256_GCM = 0x0002;
+    public static final int AEAD_CHACHA20_POLY1305 = 0x0003;
+
+    private static final byte[] VERSION_LABEL = "HPKE-v1".getBytes(StandardCharsets.US_ASCII);
+    private static final byte[] KEM_SUITE_ID = {'K', 'E', 'M', 0x00, 0x20};
+    private static final int X25519_LENGTH = 32;
+    private static final int NONCE_LENGTH = 12;
+    private static final int MODE_BASE = 0x00;
+
+    private HPKE()
+    {
+    }
+
+    /**
+     * @param kemId the key encapsulation mechanism id
+     * @param kdfId the key derivation function id
+     * @param aeadId the AEAD algorithm id
+     * @return whether the given cipher suite is supported
+     */
+    public static boolean isSupported(int kemId, int kdfId, int aeadId)
+    {
+        return kemId == KEM_X25519_HKDF_SHA256 &&
+            kdfId >= KDF_HKDF_SHA256 && kdfId <= KDF_HKDF_SHA512 &&
+            aeadId >= AEAD_AES_128_GCM && aeadId <= AEAD_CHACHA20_POLY1305;
+    }
+
+    /**
+     * @return a new X25519 key pair
+     * @throws GeneralSecurityException if X25519 is not supported by the JVM
+     */
+    public static KeyPair generateKeyPair() throws GeneralSecurityException
+    {
+        return KeyPairGenerator.getInstance("X25519").generateKeyPair();
+    }
+
+    /**
+     * @param publicKey the X25519 public key
+     * @return the 32 bytes encoding of the public key, as defined by RFC 7748
+     */
+    public static byte[] encodePublicKey(PublicKey publicKey)
+    {
+        BigInteger u = ((XECPublicKey)publicKey).getU();
+        byte[] bigEndian = u.toByteArray();
+        byte[] result = new byte[X25519_LENGTH];
+        for (int i = 0; i < X25519_LENGTH && i < bigEndian.length; ++i)
+        {
+            result[i] = bigEndian[bigEndian.length - 1 - i];
+        }
+        return result;
+    }
+
+    /**
+     * @param privateKey the X25519 private key
+     * @return the 32 bytes encoding of the public key corresponding to the given private key
+     * @throws GeneralSecurityException if the public key cannot be computed
+     */
+    public static byte[] encodePublicKey(PrivateKey privateKey) throws GeneralSecurityException
+    {
+        // The public key is the result of X25519 with the base point u=9.
+        byte[] basePoint = new byte[X25519_LENGTH];
+        basePoint[0] = 9;
+        return dh(privateKey, decodePublicKey(basePoint));
+    }
+
+    /**
+     * @param encoded the 32 bytes encoding of a X25519 public key
+     * @return the X25519 public key
+     * @throws GeneralSecurityException if the encoding is invalid
+     */
+    public static PublicKey decodePublicKey(byte[] encoded) throws GeneralSecurityException
+    {
+        if (encoded.length != X25519_LENGTH)
+            throw new GeneralSecurityException("Invalid X25519 public key length " + encoded.length);
+        byte[] bigEndian = new byte[X25519_LENGTH];
+        for (int i = 0; i < X25519_LENGTH; ++i)
+        {
+            bigEndian[i] = encoded[X25519_LENGTH - 1 - i];
+        }
+        // RFC 7748, section 5: the most significant bit is masked.
+        bigEndian[0] &= 0x7F;
+        XECPublicKeySpec spec = new XECPublicKeySpec(NamedParameterSpec.X25519, new BigInteger(1, bigEndian));
+        return KeyFactory.getInstance("XDH").generatePublic(spec);
+    }
+
+    /**
+     * <p>Sets up the sender context in base mode, RFC 9180 section 5.1.1.</p>
+     *
+     * @param kdfId the key derivation function id
+     * @param aeadId the AEAD algorithm id
+     * @param publicKey the encoded public key of the recipient
+     * @param info the application information bound to the context
+     * @return the sender context
+     * @throws GeneralSecurityException if the context cannot be set up
+     */
+    public static Sender setupBaseS(int kdfId, int aeadId, byte[] publicKey, byte[] info) throws GeneralSecurityException
+    {
+        checkSupported(kdfId, aeadId);
+        KeyPair ephemeral = generateKeyPair();
+        byte[] enc = encodePublicKey(ephemeral.getPublic());
+        byte[] dh = dh(ephemeral.getPrivate(), decodePublicKey(publicKey));
+        byte[] sharedSecret = extractAndExpand(dh, concat(enc, publicKey));
+        return new Sender(enc, keySchedule(kdfId, aeadId, sharedSecret, info));
+    }
+
+    /**
+     * <p>Sets up the recipient context in base mode, RFC 9180 section 5.1.1.</p>
+     *
+     * @param kdfId the key derivation function id
+     * @param aeadId the AEAD algorithm id
+     * @param privateKey the private key of the recipient
+     * @param enc the encapsulated key sent by the sender
+     * @param info the application information bound to the context
+     * @return the recipient context
+     * @throws GeneralSecurityException if the context cannot be set up
+     */
+    public static Context setupBaseR(int kdfId, int aeadId, PrivateKey privateKey, byte[] enc, byte[] info) throws GeneralSecurityException
+    {
+        checkSupported(kdfId, aeadId);
+        byte[] dh = dh(privateKey, decodePublicKey(enc));
+        byte[] sharedSecret = extractAndExpand(dh, concat(enc, encodePublicKey(privateKey)));
+        return keySchedule(kdfId, aeadId, sharedSecret, info);
+    }
+
+    private static void checkSupported(int kdfId, int aeadId) throws GeneralSecurityException
+    {
+        if (!isSupported(KEM_X25519_HKDF_SHA256, kdfId, aeadId))
+            throw new GeneralSecurityException(String.format("Unsupported HPKE cipher suite kdf=0x%04x aead=0x%04x", kdfId, aeadId));
+    }
+
+    private static byte[] dh(PrivateKey privateKey, PublicKey publicKey) throws GeneralSecurityException
+    {
+        KeyAgreement agreement = KeyAgreement.getInstance("XDH");
+        agreement.init(privateKey);
+        agreement.doPhase(publicKey, true);
+        return agreement.generateSecret();
+    }
+
+    private static byte[] extractAndExpand(byte[] dh, byte[] kemContext) throws GeneralSecurityException
+    {
+        byte[] prk = labeledExtract("HmacSHA256", KEM_SUITE_ID, new byte[0], "eae_prk", dh);
+        return labeledExpand("HmacSHA256", KEM_SUITE_ID, prk, "shared_secret", kemContext, X25519_LENGTH);
+    }
+
+    private static Context keySchedule(int kdfId, int aeadId, byte[] sharedSecret, byte[] info) throws GeneralSecurityException
+    {
+        String mac = kdfId == KDF_HKDF_SHA256 ? "HmacSHA256" : kdfId == KDF_HKDF_SHA384 ? "HmacSHA384" : "HmacSHA512";
+        byte[] suiteId = {'H', 'P', 'K', 'E', 0x00, 0x20, 0x00, (byte)kdfId, 0x00, (byte)aeadId};
+        byte[] pskIdHash = labeledExtract(mac, suiteId, new byte[0], "psk_id_hash", new byte[0]);
+        byte[] infoHash = labeledExtract(mac, suiteId, new byte[0], "info_hash", info);
+        byte[] context = concat(new byte[]{MODE_BASE}, concat(pskIdHash, infoHash));
+        byte[] secret = labeledExtract(mac, suiteId, sharedSecret, "secret", new byte[0]);
+        int keyLength = aeadId == AEAD_AES_128_GCM ? 16 : 32;
+        byte[] key = labeledExpand(mac, suiteId, secret, "key", context, keyLength);
+        byte[] baseNonce = labeledExpand(mac, suiteId, secret, "base_nonce", context, NONCE_LENGTH);
+        byte[] exporterSecret = labeledExpand(mac, suiteId, secret, "exp", context, Mac.getInstance(mac).getMacLength());
+        return new Context(mac, suiteId, aeadId, key, baseNonce, exporterSecret);
+    }
+
+    private static byte[] labeledExtract(String mac, byte[] suiteId, byte[] salt, String label, byte[] ikm) throws GeneralSecurityException
+    {
+        ByteArrayOutputStream labeledIkm = new ByteArrayOutputStream();
+        labeledIkm.writeBytes(VERSION_LABEL);
+        labeledIkm.writeBytes(suiteId);
+        labeledIkm.writeBytes(label.getBytes(StandardCharsets.US_ASCII));
+        labeledIkm.writeBytes(ikm);
+        return extract(mac, salt, labeledIkm.toByteArray());
+    }
+
+    private static byte[] labeledExpand(String mac, byte[] suiteId, byte[] prk, String label, byte[] info, int length) throws GeneralSecurityException
+    {
+        ByteArrayOutputStream labeledInfo = new ByteArrayOutputStream();
+        labeledInfo.write(length >>> 8);
+        labeledInfo.write(length);
+        labeledInfo.writeBytes(VERSION_LABEL);
+        labeledInfo.writeBytes(suiteId);
+        labeledInfo.writeBytes(label.getBytes(StandardCharsets.US_ASCII));
+        labeledInfo.writeBytes(info);
+        return expand(mac, prk, labeledInfo.toByteArray(), length);
+    }
+
+    private static byte[] extract(String algorithm, byte[] salt, byte[] ikm) throws GeneralSecurityException
+    {
+        Mac mac = Mac.getInstance(algorithm);
+        // An empty salt is equivalent to a salt of zeros of the hash length.
+        mac.init(new SecretKeySpec(salt.length == 0 ? new byte[mac.getMacLength()] : salt, algorithm));
+        return mac.doFinal(ikm);
+    }
+
+    private static byte[] expand(String algorithm, byte[] prk, byte[] info, int length) throws GeneralSecurityException
+    {
+        Mac mac = Mac.getInstance(algorithm);
+        mac.init(new SecretKeySpec(prk, algorithm));
+        byte[] result = new byte[length];
+        byte[] block = new byte[0];
+        int offset = 0;
+        for (int i = 1; offset < length; ++i)
+        {
+            mac.update(block);
+            mac.update(info);
+            mac.update((byte)i);
+            block = mac.doFinal();
+            int bytes = Math.min(block.length, length - offset);
+            System.arraycopy(block, 0, result, offset, bytes);
+            offset += bytes;
+        }
+        return result;
+    }
+
+    private static byte[] concat(byte[] a, byte[] b)
+    {
+        byte[] result = new byte[a.length + b.length];
+        System.arraycopy(a, 0, result, 0, a.length);
+        System.arraycopy(b, 0, result, a.length, b.length);
+        return result;
+    }
+
+    /**
+     * <p>An HPKE encryption context, that encrypts or decrypts a sequence of messages.</p>
+     * <p>Messages must be decrypted in the same order they have been encrypted.
+     * Instances of this class are not thread-safe.</p>
+     */
+    public static class Context
+    {
+        private final String _mac;
+        private final byte[] _suiteId;
+        private final int _aeadId;
+        private final byte[] _key;
+        private final byte[] _baseNonce;
+        private final byte[] _exporterSecret;
+        private long _sequence;
+
+        private Context(String mac, byte[] suiteId, int aeadId, byte[] key, byte[] baseNonce, byte[] exporterSecret)
+        {
+            _mac = mac;
+            _suiteId = suiteId;
+            _aeadId = aeadId;
+            _key = key;
+            _baseNonce = baseNonce;
+            _exporterSecret = exporterSecret;
+        }
+
+        /**
+         * @param aad the additional authenticated data
+         * @param plainText the message to encrypt
+         * @return the encrypted message
+         * @throws GeneralSecurityException if the message cannot be encrypted
+         */
+        public byte[] seal(byte[] aad, byte[] plainText) throws GeneralSecurityException
+        {
+            byte[] result = newCipher(Cipher.ENCRYPT_MODE, aad).doFinal(plainText);
+            ++_sequence;
+            return result;
+        }
+
+        /**
+         * @param aad the additional authenticated data
+         * @param cipherText the encrypted message
+         * @return the decrypted message
+         * @throws GeneralSecurityException if the message cannot be decrypted or authenticated
+         */
+        public byte[] open(byte[] aad, byte[] cipherText) throws GeneralSecurityException
+        {
+            byte[] result = newCipher(Cipher.DECRYPT_MODE, aad).doFinal(cipherText);
+            ++_sequence;
+            return result;
+        }
+
+        /**
+         * @param exporterContext the exporter context
+         * @param length the number of bytes of the secret
+         * @return a secret derived from this context, RFC 9180 section 5.3
+         * @throws GeneralSecurityException if the secret cannot be derived
+         */
+        public byte[] export(byte[] exporterContext, int length) throws GeneralSecurityException
+        {
+            return labeledExpand(_mac, _suiteId, _exporterSecret, "sec", exporterContext, length);
+        }
+
+        private Cipher newCipher(int mode, byte[] aad) throws GeneralSecurityException
+        {
+            if (_sequence == Long.MAX_VALUE)
+                throw new GeneralSecurityException("HPKE message limit reached");
+            byte[] nonce = _baseNonce.clone();
+            for (int i = 0; i < Long.BYTES; ++i)
+            {
+                nonce[NONCE_LENGTH - 1 - i] ^= (byte)(_sequence >>> (8 * i));
+            }
+            Cipher cipher;
+            AlgorithmParameterSpec spec;
+            if (_aeadId == AEAD_CHACHA20_POLY1305)
+            {
+                cipher = Cipher.getInstance("ChaCha20-Poly1305");
+                spec = new IvParameterSpec(nonce);
+                cipher.init(mode, new SecretKeySpec(_key, "ChaCha20"), spec);
+            }
+            else
+            {
+                cipher = Cipher.getInstance("AES/GCM/NoPadding");
+                spec = new GCMParameterSpec(128, nonce);
+                cipher.init(mode, new SecretKeySpec(_key, "AES"), spec);
+            }
+            cipher.updateAAD(aad);
+            return cipher;
+        }
+    }
+
+    /**
+     * <p>The sender HPKE context, along with the encapsulated key to transmit to the recipient.</p>
+     */
+    public static class Sender extends Context
+    {
+        private final byte[] _encapsulatedKey;
+
+        private Sender(byte[] encapsulatedKey, Context context)
+        {
+            super(context._mac, context._suiteId, context._aeadId, context._key, context._baseNonce, context._exporterSecret);
+            _encapsulatedKey = encapsulatedKey;
+        }
+
+        /**
+         * @return the encapsulated key, known as {@code enc} in RFC 9180
+         */
+        public byte[] getEncapsulatedKey()
+        {
+            return _encapsulatedKey.clone();
+        }
+    }
+}
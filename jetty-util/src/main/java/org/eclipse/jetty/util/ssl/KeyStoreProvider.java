@@ -0,0 +1,50 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.nio.file.Path;
+import java.security.KeyStore;
+import java.util.List;
+
+/**
+ * <p>A programmatic source of the key store used by a {@link SslContextFactory}.</p>
+ * <p>The provider is invoked every time the {@link SslContextFactory} is started or
+ * {@link SslContextFactory#reload(java.util.function.Consumer) reloaded}, so that
+ * certificates and keys can be replaced without restarting the connectors;
+ * only new TLS handshakes use the new key store.</p>
+ * <p>Providers that load the key store from files may return the files
+ * from {@link #getPaths()}, so that {@link KeyStoreScanner} reloads the
+ * {@link SslContextFactory} when the files change.</p>
+ *
+ * @see SslContextFactory#setKeyStoreSource(KeyStoreProvider)
+ * @see PemKeyStoreProvider
+ */
+@FunctionalInterface
+public interface KeyStoreProvider
+{
+    /**
+     * @param sslContextFactory the {@link SslContextFactory} that requires the key store
+     * @return a key store containing the certificates and keys
+     * @throws Exception if the key store cannot be provided
+     */
+    KeyStore getKeyStore(SslContextFactory sslContextFactory) throws Exception;
+
+    /**
+     * @return the files the key store is loaded from, to be monitored for changes
+     */
+    default List<Path> getPaths()
+    {
+        return List.of();
+    }
+}
@@ -16,6 +16,8 @@ package org.eclipse.jetty.util.ssl;
 import java.io.File;
 import java.io.IOException;
 import java.nio.file.Path;
+import java.util.ArrayList;
+import java.util.List;
 import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.TimeUnit;
 import java.util.function.Consumer;
@@ -32,6 +34,9 @@ import org.slf4j.LoggerFactory;
 /**
  * <p>The {@link KeyStoreScanner} is used to monitor the KeyStore file used by the {@link SslContextFactory}.
  * It will reload the {@link SslContextFactory} if it detects that the KeyStore file has been modified.</p>
+ * <p>If the {@link SslContextFactory} has a {@link SslContextFactory#setKeyStoreSource(KeyStoreProvider)
+ * key store source}, then the {@link KeyStoreProvider#getPaths() files} of the source are monitored
+ * instead, for example the PEM files of a {@link PemKeyStoreProvider}.</p>
  * <p>If the TrustStore file needs to be changed, then this should be done before touching the KeyStore file,
  * the {@link SslContextFactory#reload(Consumer)} will only occur after the KeyStore file has been modified.</p>
  */
@@ -40,36 +45,43 @@ public class KeyStoreScanner extends ContainerLifeCycle implements Scanner.Discr
     private static final Logger LOG = LoggerFactory.getLogger(KeyStoreScanner.class);
 
     private final SslContextFactory sslContextFactory;
-    private final File keystoreFile;
+    private final List<File> monitoredFiles = new ArrayList<>();
     private final Scanner _scanner;
 
     public KeyStoreScanner(SslContextFactory sslContextFactory)
     {
         this.sslContextFactory = sslContextFactory;
-        try
+        KeyStoreProvider keyStoreSource = sslContextFactory.getKeyStoreSource();
+        if (keyStoreSource != null && !keyStoreSource.getPaths().isEmpty())
         {
-            Resource keystoreResource = sslContextFactory.getKeyStoreResource();
-            File monitoredFile = keystoreResource.getFile();
-            if (monitoredFile == null || !monitoredFile.exists())
-                throw new IllegalArgumentException("keystore file does not exist");
-            if (monitoredFile.isDirectory())
-                throw new IllegalArgumentException("expected keystore file not directory");
-
-            keystoreFile = monitoredFile;
-            if (LOG.isDebugEnabled())
-                LOG.debug("Monitored Keystore File: {}", monitoredFile);
+            for (Path path : keyStoreSource.getPaths())
+            {
+                addMonitoredFile(path.toAbsolutePath().toFile());
+            }
         }
-        catch (IOException e)
+        else
         {
-            throw new IllegalArgumentException("could not obtain keystore file", e);
+            try
+            {
+                Resource keystoreResource = sslContextFactory.getKeyStoreResource();
+                if (keystoreResource == null)
+                    throw new IllegalArgumentException("no keystore configured");
+                addMonitoredFile(keystoreResource.getFile());
+            }
+            catch (IOException e)
+            {
+                throw new IllegalArgumentException("could not obtain keystore file", e);
+            }
         }
 
-        File parentFile = keystoreFile.getParentFile();
-        if (!parentFile.exists() || !parentFile.isDirectory())
-            throw new IllegalArgumentException("error obtaining keystore dir");
-
         _scanner = new Scanner(null, false);
-        _scanner.addDirectory(parentFile.toPath());
+        for (File file : monitoredFiles)
+        {
+            File parentFile = file.getParentFile();
+            if (!parentFile.exists() || !parentFile.isDirectory())
+                throw new IllegalArgumentException("error obtaining keystore dir");
+            _scanner.addDirectory(parentFile.toPath());
+        }
         _scanner.setScanInterval(1);
         _scanner.setReportDirs(false);
         _scanner.setReportExistingFilesOnStartup(false);
@@ -78,25 +90,51 @@ public class KeyStoreScanner extends ContainerLifeCycle implements Scanner.Discr
         addBean(_scanner);
     }
 
-    private Path getRealKeyStorePath()
+    private void addMonitoredFile(File monitoredFile)
     {
-        try
+        if (monitoredFile == null || !monitoredFile.exists())
+            throw new IllegalArgumentException("keystore file does not exist");
+        if (monitoredFile.isDirectory())
+            throw new IllegalArgumentException("expected keystore file not directory");
+        monitoredFiles.add(monitoredFile);
+        if (LOG.isDebugEnabled())
+            LOG.debug("Monitored Keystore File: {}", monitoredFile);
+    }
+
+    private boolean isMonitored(String filename)
+    {
+        for (File file : monitoredFiles)
         {
-            return keystoreFile.toPath().toRealPath();
+            if (file.toPath().toString().equals(filename))
+                return true;
         }
-        catch (IOException e)
+        return false;
+    }
+
+    private List<Path> getRealKeyStorePaths()
+    {
+        List<Path> paths = new ArrayList<>();
+        for (File file : monitoredFiles)
         {
-            return keystoreFile.toPath();
+            try
+            {
+                paths.add(file.toPath().toRealPath());
+            }
+            catch (IOException e)
+            {
+                paths.add(file.toPath());
+            }
         }
+        return paths;
     }
 
     @Override
     public void fileAdded(String filename)
     {
         if (LOG.isDebugEnabled())
-            LOG.debug("fileAdded {} - keystoreFile.toReal {}", filename, getRealKeyStorePath());
+            LOG.debug("fileAdded {} - keystoreFile.toReal {}", filename, getRealKeyStorePaths());
 
-        if (keystoreFile.toPath().toString().equals(filename))
+        if (isMonitored(filename))
             reload();
     }
 
@@ -104,9 +142,9 @@ public class KeyStoreScanner extends ContainerLifeCycle implements Scanner.Discr
     public void fileChanged(String filename)
     {
         if (LOG.isDebugEnabled())
-            LOG.debug("fileChanged {} - keystoreFile.toReal {}", filename, getRealKeyStorePath());
+            LOG.debug("fileChanged {} - keystoreFile.toReal {}", filename, getRealKeyStorePaths());
 
-        if (keystoreFile.toPath().toString().equals(filename))
+        if (isMonitored(filename))
             reload();
     }
 
@@ -114,9 +152,9 @@ public class KeyStoreScanner extends ContainerLifeCycle implements Scanner.Discr
     public void fileRemoved(String filename)
     {
         if (LOG.isDebugEnabled())
-            LOG.debug("fileRemoved {} - keystoreFile.toReal {}", filename, getRealKeyStorePath());
+            LOG.debug("fileRemoved {} - keystoreFile.toReal {}", filename, getRealKeyStorePaths());
 
-        if (keystoreFile.toPath().toString().equals(filename))
+        if (isMonitored(filename))
             reload();
     }
 
@@ -144,7 +182,7 @@ public class KeyStoreScanner extends ContainerLifeCycle implements Scanner.Discr
     public void reload()
     {
         if (LOG.isDebugEnabled())
-            LOG.debug("reloading keystore file {}", keystoreFile);
+            LOG.debug("reloading keystore files {}", monitoredFiles);
 
         try
         {
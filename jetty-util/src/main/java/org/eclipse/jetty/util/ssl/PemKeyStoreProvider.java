@@ -0,0 +1,222 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.ByteArrayInputStream;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.security.GeneralSecurityException;
+import java.security.InvalidKeyException;
+import java.security.KeyFactory;
+import java.security.KeyStore;
+import java.security.PrivateKey;
+import java.security.PublicKey;
+import java.security.SecureRandom;
+import java.security.Signature;
+import java.security.SignatureException;
+import java.security.cert.Certificate;
+import java.security.cert.CertificateFactory;
+import java.security.spec.MGF1ParameterSpec;
+import java.security.spec.PKCS8EncodedKeySpec;
+import java.security.spec.PSSParameterSpec;
+import java.util.ArrayList;
+import java.util.Base64;
+import java.util.List;
+import java.util.Objects;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link KeyStoreProvider} that loads the certificate chain and the
+ * private key from PEM files, as typically produced by ACME clients.</p>
+ * <p>The certificate chain file contains one or more {@code CERTIFICATE}
+ * blocks, starting with the end entity certificate; the private key file
+ * contains an unencrypted PKCS#8 {@code PRIVATE KEY} block.
+ * Both may be in the same file, and the private key must match the public
+ * key of the end entity certificate.</p>
+ * <p>The private key entry is protected with the key manager password, or the
+ * key store password, configured on the {@link SslContextFactory}.</p>
+ * <p>Use a {@link KeyStoreScanner} to reload the {@link SslContextFactory}
+ * when the PEM files are renewed.</p>
+ */
+public class PemKeyStoreProvider implements KeyStoreProvider
+{
+    private static final Logger LOG = LoggerFactory.getLogger(PemKeyStoreProvider.class);
+    private static final Pattern PEM_BLOCK = Pattern.compile("-----BEGIN ([A-Z0-9 ]+)-----([^-]*)-----END \\1-----");
+    private static final String[] KEY_ALGORITHMS = {"RSA", "EC", "EdDSA", "RSASSA-PSS", "DSA"};
+
+    private final Path _certificateChainPath;
+    private final Path _privateKeyPath;
+    private String _alias = "pem";
+
+    public PemKeyStoreProvider(Path certificateChainPath, Path privateKeyPath)
+    {
+        _certificateChainPath = Objects.requireNonNull(certificateChainPath);
+        _privateKeyPath = Objects.requireNonNull(privateKeyPath);
+    }
+
+    public Path getCertificateChainPath()
+    {
+        return _certificateChainPath;
+    }
+
+    public Path getPrivateKeyPath()
+    {
+        return _privateKeyPath;
+    }
+
+    public String getAlias()
+    {
+        return _alias;
+    }
+
+    /**
+     * @param alias the alias of the key store entry
+     */
+    public void setAlias(String alias)
+    {
+        _alias = Objects.requireNonNull(alias);
+    }
+
+    @Override
+    public KeyStore getKeyStore(SslContextFactory sslContextFactory) throws Exception
+    {
+        String password = sslContextFactory.getKeyManagerPassword();
+        if (password == null)
+            password = sslContextFactory.getKeyStorePassword();
+        if (password == null)
+            throw new IllegalStateException("No key store password configured for " + sslContextFactory);
+
+        List<Certificate> certificates = new ArrayList<>();
+        CertificateFactory certificateFactory = CertificateFactory.getInstance("X.509");
+        for (byte[] bytes : readBlocks(_certificateChainPath, "CERTIFICATE"))
+        {
+            certificates.add(certificateFactory.generateCertificate(new ByteArrayInputStream(bytes)));
+        }
+        if (certificates.isEmpty())
+            throw new IllegalArgumentException("No certificates in " + _certificateChainPath);
+
+        List<byte[]> keys = readBlocks(_privateKeyPath, "PRIVATE KEY");
+        if (keys.size() != 1)
+            throw new IllegalArgumentException("Expected one unencrypted PKCS#8 private key in " + _privateKeyPath);
+        PrivateKey privateKey = toPrivateKey(keys.get(0));
+        if (!matches(privateKey, certificates.get(0).getPublicKey()))
+            throw new IllegalArgumentException("Private key in " + _privateKeyPath + " does not match the certificate in " + _certificateChainPath);
+
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        keyStore.load(null, null);
+        keyStore.setKeyEntry(_alias, privateKey, password.toCharArray(), certificates.toArray(new Certificate[0]));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Loaded {} certificates and {} private key from {} and {}", certificates.size(), privateKey.getAlgorithm(), _certificateChainPath, _privateKeyPath);
+        return keyStore;
+    }
+
+    @Override
+    public List<Path> getPaths()
+    {
+        if (_certificateChainPath.equals(_privateKeyPath))
+            return List.of(_certificateChainPath);
+        return List.of(_certificateChainPath, _privateKeyPath);
+    }
+
+    private static List<byte[]> readBlocks(Path path, String type) throws Exception
+    {
+        String pem = Files.readString(path, StandardCharsets.US_ASCII);
+        List<byte[]> result = new ArrayList<>();
+        Matcher matcher = PEM_BLOCK.matcher(pem);
+        while (matcher.find())
+        {
+            if (type.equals(matcher.group(1)))
+                result.add(Base64.getMimeDecoder().decode(matcher.group(2)));
+        }
+        return result;
+    }
+
+    private static PrivateKey toPrivateKey(byte[] bytes) throws GeneralSecurityException
+    {
+        PKCS8EncodedKeySpec keySpec = new PKCS8EncodedKeySpec(bytes);
+        GeneralSecurityException failure = null;
+        for (String algorithm : KEY_ALGORITHMS)
+        {
+            try
+            {
+                return KeyFactory.getInstance(algorithm).generatePrivate(keySpec);
+            }
+            catch (GeneralSecurityException x)
+            {
+                if (failure == null)
+                    failure = x;
+                else
+                    failure.addSuppressed(x);
+            }
+        }
+        throw failure;
+    }
+
+    private static boolean matches(PrivateKey privateKey, PublicKey publicKey) throws GeneralSecurityException
+    {
+        // Sign random data with the private key and verify it with the public key.
+        Signature signer = newSignature(privateKey.getAlgorithm());
+        Signature verifier = newSignature(privateKey.getAlgorithm());
+        byte[] data = new byte[32];
+        new SecureRandom().nextBytes(data);
+        try
+        {
+            signer.initSign(privateKey);
+            signer.update(data);
+            byte[] signature = signer.sign();
+            verifier.initVerify(publicKey);
+            verifier.update(data);
+            return verifier.verify(signature);
+        }
+        catch (InvalidKeyException | SignatureException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Could not verify {} private key against {} public key", privateKey.getAlgorithm(), publicKey.getAlgorithm(), x);
+            return false;
+        }
+    }
+
+    private static Signature newSignature(String keyAlgorithm) throws GeneralSecurityException
+    {
+        switch (keyAlgorithm)
+        {
+            case "RSA":
+                return Signature.getInstance("SHA256withRSA");
+            case "EC":
+                return Signature.getInstance("SHA256withECDSA");
+            case "DSA":
+                return Signature.getInstance("SHA256withDSA");
+            case "RSASSA-PSS":
+            {
+                Signature signature = Signature.getInstance("RSASSA-PSS");
+                signature.setParameter(new PSSParameterSpec("SHA-256", "MGF1", MGF1ParameterSpec.SHA256, 32, 1));
+                return signature;
+            }
+            default:
+                // EdDSA and other algorithms where the key determines the signature.
+                return Signature.getInstance(keyAlgorithm);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), _certificateChainPath, _privateKeyPath);
+    }
+}
@@ -0,0 +1,1079 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.math.BigInteger;
+import java.net.HttpURLConnection;
+import java.net.Socket;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.security.KeyStore;
+import java.security.KeyStoreException;
+import java.security.MessageDigest;
+import java.security.cert.CRL;
+import java.security.cert.CertPath;
+import java.security.cert.CertPathValidator;
+import java.security.cert.CertPathValidatorException;
+import java.security.cert.CertStore;
+import java.security.cert.Certificate;
+import java.security.cert.CertificateException;
+import java.security.cert.CertificateFactory;
+import java.security.cert.CollectionCertStoreParameters;
+import java.security.cert.PKIXParameters;
+import java.security.cert.PKIXRevocationChecker;
+import java.security.cert.TrustAnchor;
+import java.security.cert.X509Certificate;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Collection;
+import java.util.Collections;
+import java.util.EnumSet;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.LongAdder;
+import javax.net.ssl.ExtendedSSLSession;
+import javax.net.ssl.SSLEngine;
+import javax.net.ssl.SSLSession;
+import javax.net.ssl.SSLSocket;
+import javax.net.ssl.X509ExtendedTrustManager;
+import javax.security.auth.x500.X500Principal;
+
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.security.CertificateUtils;
+import org.eclipse.jetty.util.thread.ScheduledExecutorScheduler;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Checks the revocation status of the certificates presented by TLS peers.</p>
+ * <p>When set on a {@link SslContextFactory} via {@link SslContextFactory#setRevocationChecker(RevocationChecker)},
+ * the trust managers of the factory are wrapped so that, once the peer certificate chain is trusted,
+ * the revocation status of each certificate of the chain is verified using, in order:</p>
+ * <ul>
+ * <li>the OCSP responses stapled by the peer to the TLS handshake;</li>
+ * <li>the OCSP responses obtained by querying the OCSP responder named in the certificate,
+ * or the {@link #setOcspResponder(String) configured responder}; these responses are cached
+ * for {@link #setOcspCacheTime(long) a configurable time};</li>
+ * <li>the CRLs loaded from {@link #setCrlPath(String)}, periodically re-downloaded
+ * every {@link #setCrlRefreshInterval(long) refresh interval}.</li>
+ * </ul>
+ * <p>A revoked certificate always fails the TLS handshake.
+ * When the revocation status cannot be determined, for example because the OCSP responder
+ * is unreachable, the {@link FailurePolicy} returned by {@link #getFailurePolicy(SSLSession, X509Certificate[])}
+ * decides whether the TLS handshake fails or proceeds.
+ * The outcome of the check is stored as a {@link Status} value of the TLS session, under the
+ * {@link #STATUS_ATTRIBUTE} name, where it can be retrieved by request customizers.</p>
+ * <p>Server certificates that carry the TLS Feature {@code status_request} extension
+ * (also known as "must-staple") are rejected if the server did not staple an OCSP response,
+ * unless {@link #setEnforceMustStaple(boolean)} is disabled.</p>
+ * <p>OCSP responders are queried from the thread performing the TLS handshake,
+ * so {@link #setOcspTimeout(long)} should be kept short.</p>
+ */
+@ManagedObject("TLS certificate revocation checker")
+public class RevocationChecker extends ContainerLifeCycle
+{
+    /**
+     * The name of the {@link SSLSession} value holding the {@link Status} of the peer certificates.
+     */
+    public static final String STATUS_ATTRIBUTE = "org.eclipse.jetty.util.ssl.revocationStatus";
+    private static final Logger LOG = LoggerFactory.getLogger(RevocationChecker.class);
+    private static final String AUTHORITY_INFO_ACCESS_OID = "1.3.6.1.5.5.7.1.1";
+    private static final String TLS_FEATURE_OID = "1.3.6.1.5.5.7.1.24";
+    // The DER encoding of OID 1.3.6.1.5.5.7.48.1 (id-ad-ocsp).
+    private static final byte[] OCSP_ACCESS_METHOD = {0x2B, 0x06, 0x01, 0x05, 0x05, 0x07, 0x30, 0x01};
+    // The DER encoding of the SHA-1 AlgorithmIdentifier.
+    private static final byte[] SHA1_ALGORITHM = {0x30, 0x09, 0x06, 0x05, 0x2B, 0x0E, 0x03, 0x02, 0x1A, 0x05, 0x00};
+    private static final int STATUS_REQUEST = 5;
+
+    private final Map<X509Certificate, CachedResponse> _ocspCache = new ConcurrentHashMap<>();
+    private final LongAdder _checks = new LongAdder();
+    private final LongAdder _rejections = new LongAdder();
+    private final LongAdder _softFailures = new LongAdder();
+    private final LongAdder _ocspRequests = new LongAdder();
+    private final LongAdder _ocspCacheHits = new LongAdder();
+    private FailurePolicy _failurePolicy = FailurePolicy.HARD_FAIL;
+    private boolean _enableOCSP = true;
+    private URI _ocspResponder;
+    private long _ocspTimeout = 5000;
+    private long _ocspCacheTime = TimeUnit.HOURS.toMillis(1);
+    private int _maxOcspCacheEntries = 1024;
+    private boolean _preferCrls;
+    private boolean _onlyEndEntity;
+    private boolean _enforceMustStaple = true;
+    private boolean _stapling;
+    private String _crlPath;
+    private long _crlRefreshInterval;
+    private Scheduler _scheduler;
+    private Scheduler.Task _crlRefreshTask;
+    private volatile CertStore _crlStore;
+    private volatile int _crlCount;
+
+    /**
+     * @return the policy applied when the revocation status cannot be determined
+     */
+    @ManagedAttribute("The policy applied when the revocation status cannot be determined")
+    public FailurePolicy getFailurePolicy()
+    {
+        return _failurePolicy;
+    }
+
+    /**
+     * @param failurePolicy the policy applied when the revocation status cannot be determined
+     */
+    public void setFailurePolicy(FailurePolicy failurePolicy)
+    {
+        _failurePolicy = failurePolicy == null ? FailurePolicy.HARD_FAIL : failurePolicy;
+    }
+
+    /**
+     * @return whether OCSP is used to check the revocation status
+     */
+    @ManagedAttribute("Whether OCSP is used to check the revocation status")
+    public boolean isEnableOCSP()
+    {
+        return _enableOCSP;
+    }
+
+    /**
+     * @param enableOCSP whether OCSP is used to check the revocation status;
+     * when {@code false} only CRLs are used
+     */
+    public void setEnableOCSP(boolean enableOCSP)
+    {
+        _enableOCSP = enableOCSP;
+    }
+
+    /**
+     * @return the OCSP responder that overrides the one named in the certificates, or {@code null}
+     */
+    @ManagedAttribute("The OCSP responder that overrides the one named in the certificates")
+    public String getOcspResponder()
+    {
+        return _ocspResponder == null ? null : _ocspResponder.toString();
+    }
+
+    /**
+     * @param ocspResponder the OCSP responder that overrides the one named in the certificates, or {@code null}
+     */
+    public void setOcspResponder(String ocspResponder)
+    {
+        _ocspResponder = ocspResponder == null || ocspResponder.isBlank() ? null : URI.create(ocspResponder);
+    }
+
+    /**
+     * @return the connect and read timeout, in milliseconds, of OCSP queries
+     */
+    @ManagedAttribute("The connect and read timeout in milliseconds of OCSP queries")
+    public long getOcspTimeout()
+    {
+        return _ocspTimeout;
+    }
+
+    /**
+     * @param ocspTimeout the connect and read timeout, in milliseconds, of OCSP queries
+     */
+    public void setOcspTimeout(long ocspTimeout)
+    {
+        _ocspTimeout = ocspTimeout;
+    }
+
+    /**
+     * @return the time, in milliseconds, OCSP responses are cached for
+     */
+    @ManagedAttribute("The time in milliseconds OCSP responses are cached for")
+    public long getOcspCacheTime()
+    {
+        return _ocspCacheTime;
+    }
+
+    /**
+     * <p>Sets the time OCSP responses obtained from OCSP responders are cached for.</p>
+     * <p>Cached responses that are no longer valid, for example because their
+     * {@code nextUpdate} time has passed, are discarded and the responder queried again.</p>
+     *
+     * @param ocspCacheTime the time, in milliseconds, OCSP responses are cached for, or 0 to disable caching
+     */
+    public void setOcspCacheTime(long ocspCacheTime)
+    {
+        _ocspCacheTime = ocspCacheTime;
+    }
+
+    /**
+     * @return the max number of cached OCSP responses
+     */
+    @ManagedAttribute("The max number of cached OCSP responses")
+    public int getMaxOcspCacheEntries()
+    {
+        return _maxOcspCacheEntries;
+    }
+
+    /**
+     * @param maxOcspCacheEntries the max number of cached OCSP responses
+     */
+    public void setMaxOcspCacheEntries(int maxOcspCacheEntries)
+    {
+        _maxOcspCacheEntries = maxOcspCacheEntries;
+    }
+
+    /**
+     * @return whether CRLs are preferred over OCSP
+     */
+    @ManagedAttribute("Whether CRLs are preferred over OCSP")
+    public boolean isPreferCrls()
+    {
+        return _preferCrls;
+    }
+
+    /**
+     * @param preferCrls whether CRLs are preferred over OCSP
+     */
+    public void setPreferCrls(boolean preferCrls)
+    {
+        _preferCrls = preferCrls;
+    }
+
+    /**
+     * @return whether only the revocation status of the peer certificate is checked
+     */
+    @ManagedAttribute("Whether only the revocation status of the peer certificate is checked")
+    public boolean isOnlyEndEntity()
+    {
+        return _onlyEndEntity;
+    }
+
+    /**
+     * @param onlyEndEntity whether only the revocation status of the peer certificate,
+     * and not that of the intermediate CA certificates, is checked
+     */
+    public void setOnlyEndEntity(boolean onlyEndEntity)
+    {
+        _onlyEndEntity = onlyEndEntity;
+    }
+
+    /**
+     * @return whether must-staple server certificates require a stapled OCSP response
+     */
+    @ManagedAttribute("Whether must-staple server certificates require a stapled OCSP response")
+    public boolean isEnforceMustStaple()
+    {
+        return _enforceMustStaple;
+    }
+
+    /**
+     * <p>Sets whether server certificates carrying the TLS Feature {@code status_request}
+     * extension are rejected when the server does not staple an OCSP response.</p>
+     * <p>When enabled, this instance also enables the JDK client support for
+     * OCSP stapling via the {@code jdk.tls.client.enableStatusRequestExtension} system property.</p>
+     *
+     * @param enforceMustStaple whether must-staple server certificates require a stapled OCSP response
+     */
+    public void setEnforceMustStaple(boolean enforceMustStaple)
+    {
+        _enforceMustStaple = enforceMustStaple;
+    }
+
+    /**
+     * @return whether the JDK server support for OCSP stapling is enabled
+     */
+    @ManagedAttribute("Whether the JDK server support for OCSP stapling is enabled")
+    public boolean isStapling()
+    {
+        return _stapling;
+    }
+
+    /**
+     * <p>Sets whether the JDK server support for OCSP stapling is enabled,
+     * via the {@code jdk.tls.server.enableStatusRequestExtension} system property.</p>
+     * <p>The system property is only read by the JDK when the first {@code SSLContext}
+     * is created, so this setting has no effect if the JDK TLS implementation
+     * has already been initialized.</p>
+     *
+     * @param stapling whether the server staples OCSP responses to its certificates
+     */
+    public void setStapling(boolean stapling)
+    {
+        _stapling = stapling;
+    }
+
+    /**
+     * @return the path or URL of the certificate revocation lists
+     */
+    @ManagedAttribute("The path or URL of the certificate revocation lists")
+    public String getCrlPath()
+    {
+        return _crlPath;
+    }
+
+    /**
+     * @param crlPath the path or URL of the certificate revocation lists
+     */
+    public void setCrlPath(String crlPath)
+    {
+        _crlPath = crlPath;
+    }
+
+    /**
+     * @return the interval, in milliseconds, between reloads of the certificate revocation lists
+     */
+    @ManagedAttribute("The interval in milliseconds between reloads of the certificate revocation lists")
+    public long getCrlRefreshInterval()
+    {
+        return _crlRefreshInterval;
+    }
+
+    /**
+     * <p>Sets the interval between reloads of the certificate revocation lists.</p>
+     * <p>If a reload fails, the previously loaded certificate revocation lists remain in use
+     * until the next successful reload.</p>
+     *
+     * @param crlRefreshInterval the interval, in milliseconds, between reloads
+     * of the certificate revocation lists, or 0 to load them only at startup
+     */
+    public void setCrlRefreshInterval(long crlRefreshInterval)
+    {
+        _crlRefreshInterval = crlRefreshInterval;
+    }
+
+    /**
+     * @return the scheduler used to reload the certificate revocation lists
+     */
+    public Scheduler getScheduler()
+    {
+        return _scheduler;
+    }
+
+    /**
+     * @param scheduler the scheduler used to reload the certificate revocation lists
+     */
+    public void setScheduler(Scheduler scheduler)
+    {
+        updateBean(_scheduler, scheduler);
+        _scheduler = scheduler;
+    }
+
+    @ManagedAttribute("The number of loaded certificate revocation lists")
+    public int getCrlCount()
+    {
+        return _crlCount;
+    }
+
+    @ManagedAttribute("The number of revocation checks")
+    public long getChecks()
+    {
+        return _checks.longValue();
+    }
+
+    @ManagedAttribute("The number of certificate chains rejected")
+    public long getRejections()
+    {
+        return _rejections.longValue();
+    }
+
+    @ManagedAttribute("The number of revocation checks that soft failed")
+    public long getSoftFailures()
+    {
+        return _softFailures.longValue();
+    }
+
+    @ManagedAttribute("The number of OCSP queries")
+    public long getOcspRequests()
+    {
+        return _ocspRequests.longValue();
+    }
+
+    @ManagedAttribute("The number of OCSP responses found in the cache")
+    public long getOcspCacheHits()
+    {
+        return _ocspCacheHits.longValue();
+    }
+
+    @ManagedAttribute("The number of cached OCSP responses")
+    public int getOcspCacheEntries()
+    {
+        return _ocspCache.size();
+    }
+
+    @ManagedOperation(value = "Clears the OCSP response cache", impact = "ACTION")
+    public void clearOcspCache()
+    {
+        _ocspCache.clear();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (isStapling())
+            System.setProperty("jdk.tls.server.enableStatusRequestExtension", "true");
+        if (isEnforceMustStaple())
+            System.setProperty("jdk.tls.client.enableStatusRequestExtension", "true");
+        if (getCrlRefreshInterval() > 0 && _scheduler == null)
+            setScheduler(new ScheduledExecutorScheduler(String.format("RevocationChecker@%x", hashCode()), true));
+        super.doStart();
+        refreshCrls();
+        scheduleCrlRefresh();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        Scheduler.Task task = _crlRefreshTask;
+        if (task != null)
+            task.cancel();
+        _crlRefreshTask = null;
+        _ocspCache.clear();
+        super.doStop();
+    }
+
+    /**
+     * <p>Reloads the certificate revocation lists from {@link #getCrlPath()}.</p>
+     *
+     * @throws Exception if the certificate revocation lists cannot be loaded
+     */
+    @ManagedOperation(value = "Reloads the certificate revocation lists", impact = "ACTION")
+    public void refreshCrls() throws Exception
+    {
+        String crlPath = getCrlPath();
+        if (crlPath == null)
+            return;
+        Collection<? extends CRL> crls = CertificateUtils.loadCRL(crlPath);
+        _crlStore = CertStore.getInstance("Collection", new CollectionCertStoreParameters(crls));
+        _crlCount = crls.size();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Loaded {} CRLs from {} for {}", crls.size(), crlPath, this);
+    }
+
+    private void scheduleCrlRefresh()
+    {
+        if (getCrlPath() != null && getCrlRefreshInterval() > 0 && isRunning())
+            _crlRefreshTask = _scheduler.schedule(this::onCrlRefresh, getCrlRefreshInterval(), TimeUnit.MILLISECONDS);
+    }
+
+    private void onCrlRefresh()
+    {
+        try
+        {
+            refreshCrls();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Unable to refresh CRLs from {}", getCrlPath(), x);
+        }
+        finally
+        {
+            scheduleCrlRefresh();
+        }
+    }
+
+    /**
+     * <p>Wraps the given trust manager so that the revocation status of trusted
+     * certificate chains is checked by this instance.</p>
+     *
+     * @param trustManager the trust manager to wrap
+     * @param trustStore the trust store holding the trust anchors
+     * @return a trust manager that also checks the revocation status
+     * @throws KeyStoreException if the trust store cannot be read
+     */
+    public X509ExtendedTrustManager newTrustManager(X509ExtendedTrustManager trustManager, KeyStore trustStore) throws KeyStoreException
+    {
+        Map<X500Principal, X509Certificate> anchors = new HashMap<>();
+        for (String alias : Collections.list(trustStore.aliases()))
+        {
+            Certificate certificate = trustStore.getCertificate(alias);
+            if (certificate instanceof X509Certificate)
+            {
+                X509Certificate x509 = (X509Certificate)certificate;
+                anchors.put(x509.getSubjectX500Principal(), x509);
+            }
+        }
+        return new RevocationTrustManager(trustManager, anchors);
+    }
+
+    /**
+     * <p>Returns the policy to apply when the revocation status of the
+     * given certificate chain cannot be determined.</p>
+     * <p>This method is called for every TLS handshake and may be overridden
+     * to apply a different policy depending on the handshake, for example
+     * depending on the SNI name found in the handshake session.</p>
+     *
+     * @param session the TLS handshake session, or {@code null} if not available
+     * @param chain the peer certificate chain
+     * @return the policy to apply, by default {@link #getFailurePolicy()}
+     */
+    protected FailurePolicy getFailurePolicy(SSLSession session, X509Certificate[] chain)
+    {
+        return getFailurePolicy();
+    }
+
+    /**
+     * <p>Sends the given DER encoded OCSP request to the given OCSP responder.</p>
+     *
+     * @param responder the OCSP responder
+     * @param request the DER encoded OCSP request
+     * @return the DER encoded OCSP response
+     * @throws IOException if the responder cannot be queried
+     */
+    protected byte[] fetchOcspResponse(URI responder, byte[] request) throws IOException
+    {
+        HttpURLConnection connection = (HttpURLConnection)responder.toURL().openConnection();
+        try
+        {
+            int timeout = (int)Math.min(Integer.MAX_VALUE, getOcspTimeout());
+            connection.setConnectTimeout(timeout);
+            connection.setReadTimeout(timeout);
+            connection.setRequestMethod("POST");
+            connection.setRequestProperty("Content-Type", "application/ocsp-request");
+            connection.setRequestProperty("Accept", "application/ocsp-response");
+            connection.setDoOutput(true);
+            connection.setFixedLengthStreamingMode(request.length);
+            try (OutputStream output = connection.getOutputStream())
+            {
+                output.write(request);
+            }
+            int status = connection.getResponseCode();
+            if (status != HttpURLConnection.HTTP_OK)
+                throw new IOException("Unexpected status " + status + " from OCSP responder " + responder);
+            try (InputStream input = connection.getInputStream())
+            {
+                return IO.readBytes(input);
+            }
+        }
+        finally
+        {
+            connection.disconnect();
+        }
+    }
+
+    private byte[] getOcspResponse(X509Certificate certificate, X509Certificate issuer, boolean useCache, Set<X509Certificate> cached)
+    {
+        if (useCache)
+        {
+            CachedResponse entry = _ocspCache.get(certificate);
+            if (entry != null)
+            {
+                if (!entry.isExpired())
+                {
+                    _ocspCacheHits.increment();
+                    cached.add(certificate);
+                    return entry._response;
+                }
+                _ocspCache.remove(certificate, entry);
+            }
+        }
+
+        URI responder = _ocspResponder != null ? _ocspResponder : getOcspResponder(certificate);
+        if (responder == null)
+            return null;
+
+        try
+        {
+            _ocspRequests.increment();
+            byte[] response = fetchOcspResponse(responder, newOcspRequest(certificate, issuer));
+            cacheOcspResponse(certificate, response);
+            return response;
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Unable to query OCSP responder {} for {}", responder, certificate.getSubjectX500Principal(), x);
+            return null;
+        }
+    }
+
+    private void cacheOcspResponse(X509Certificate certificate, byte[] response)
+    {
+        long cacheTime = getOcspCacheTime();
+        if (cacheTime <= 0)
+            return;
+        if (_ocspCache.size() >= getMaxOcspCacheEntries())
+            _ocspCache.values().removeIf(CachedResponse::isExpired);
+        if (_ocspCache.size() < getMaxOcspCacheEntries())
+            _ocspCache.put(certificate, new CachedResponse(response, NanoTime.now() + TimeUnit.MILLISECONDS.toNanos(cacheTime)));
+    }
+
+    private List<CertPathValidatorException> validate(List<X509Certificate> path, X509Certificate anchor, Map<X509Certificate, byte[]> responses, FailurePolicy policy) throws GeneralSecurityException
+    {
+        CertPathValidator validator = CertPathValidator.getInstance("PKIX");
+        PKIXRevocationChecker checker = (PKIXRevocationChecker)validator.getRevocationChecker();
+        Set<PKIXRevocationChecker.Option> options = EnumSet.noneOf(PKIXRevocationChecker.Option.class);
+        if (policy == FailurePolicy.SOFT_FAIL)
+            options.add(PKIXRevocationChecker.Option.SOFT_FAIL);
+        if (isOnlyEndEntity())
+            options.add(PKIXRevocationChecker.Option.ONLY_END_ENTITY);
+        if (!isEnableOCSP())
+        {
+            options.add(PKIXRevocationChecker.Option.PREFER_CRLS);
+            options.add(PKIXRevocationChecker.Option.NO_FALLBACK);
+        }
+        else if (isPreferCrls())
+        {
+            options.add(PKIXRevocationChecker.Option.PREFER_CRLS);
+        }
+        checker.setOptions(options);
+        checker.setOcspResponses(responses);
+        if (_ocspResponder != null)
+            checker.setOcspResponder(_ocspResponder);
+
+        PKIXParameters parameters = new PKIXParameters(Set.of(new TrustAnchor(anchor, null)));
+        parameters.setRevocationEnabled(true);
+        parameters.addCertPathChecker(checker);
+        CertStore crlStore = _crlStore;
+        if (crlStore != null)
+            parameters.addCertStore(crlStore);
+
+        CertPath certPath = CertificateFactory.getInstance("X.509").generateCertPath(path);
+        validator.validate(certPath, parameters);
+        return checker.getSoftFailExceptions();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[policy=%s,ocsp=%b,crlPath=%s]", getClass().getSimpleName(), hashCode(), getFailurePolicy(), isEnableOCSP(), getCrlPath());
+    }
+
+    /**
+     * @param certificate the certificate
+     * @return whether the certificate carries the TLS Feature {@code status_request} extension
+     */
+    public static boolean isMustStaple(X509Certificate certificate)
+    {
+        byte[] extension = certificate.getExtensionValue(TLS_FEATURE_OID);
+        if (extension == null)
+            return false;
+        try
+        {
+            for (Der feature : Der.parse(Der.parse(extension).value()).children())
+            {
+                if (feature._tag == 0x02 && new BigInteger(feature.value()).intValue() == STATUS_REQUEST)
+                    return true;
+            }
+        }
+        catch (RuntimeException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid TLS Feature extension in {}", certificate.getSubjectX500Principal(), x);
+        }
+        return false;
+    }
+
+    static URI getOcspResponder(X509Certificate certificate)
+    {
+        byte[] extension = certificate.getExtensionValue(AUTHORITY_INFO_ACCESS_OID);
+        if (extension == null)
+            return null;
+        try
+        {
+            for (Der accessDescription : Der.parse(Der.parse(extension).value()).children())
+            {
+                List<Der> fields = accessDescription.children();
+                // The accessLocation must be a uniformResourceIdentifier, tagged [6].
+                if (fields.size() == 2 &&
+                    fields.get(0)._tag == 0x06 &&
+                    Arrays.equals(fields.get(0).value(), OCSP_ACCESS_METHOD) &&
+                    fields.get(1)._tag == 0x86)
+                    return URI.create(new String(fields.get(1).value(), StandardCharsets.US_ASCII));
+            }
+        }
+        catch (RuntimeException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Invalid Authority Information Access extension in {}", certificate.getSubjectX500Principal(), x);
+        }
+        return null;
+    }
+
+    static byte[] newOcspRequest(X509Certificate certificate, X509Certificate issuer) throws GeneralSecurityException
+    {
+        MessageDigest sha1 = MessageDigest.getInstance("SHA-1");
+        byte[] issuerNameHash = sha1.digest(issuer.getSubjectX500Principal().getEncoded());
+        // SubjectPublicKeyInfo ::= SEQUENCE { algorithm AlgorithmIdentifier, subjectPublicKey BIT STRING }
+        byte[] publicKey = Der.parse(issuer.getPublicKey().getEncoded()).children().get(1).value();
+        // Skip the BIT STRING unused bits byte.
+        sha1.update(publicKey, 1, publicKey.length - 1);
+        byte[] issuerKeyHash = sha1.digest();
+        byte[] certId = der(0x30, SHA1_ALGORITHM, der(0x04, issuerNameHash), der(0x04, issuerKeyHash), der(0x02, certificate.getSerialNumber().toByteArray()));
+        // OCSPRequest { TBSRequest { requestList { Request { CertID } } } }
+        return der(0x30, der(0x30, der(0x30, der(0x30, certId))));
+    }
+
+    private static byte[] der(int tag, byte[]... contents)
+    {
+        ByteArrayOutputStream value = new ByteArrayOutputStream();
+        for (byte[] content : contents)
+        {
+            value.writeBytes(content);
+        }
+        int length = value.size();
+        ByteArrayOutputStream result = new ByteArrayOutputStream();
+        result.write(tag);
+        if (length < 0x80)
+        {
+            result.write(length);
+        }
+        else
+        {
+            int bytes = (Integer.SIZE - Integer.numberOfLeadingZeros(length) + 7) / 8;
+            result.write(0x80 | bytes);
+            for (int i = bytes - 1; i >= 0; --i)
+            {
+                result.write(length >>> (8 * i));
+            }
+        }
+        result.writeBytes(value.toByteArray());
+        return result.toByteArray();
+    }
+
+    /**
+     * <p>The policy applied when the revocation status of a certificate cannot be determined.</p>
+     */
+    public enum FailurePolicy
+    {
+        /**
+         * The TLS handshake fails.
+         */
+        HARD_FAIL,
+        /**
+         * The TLS handshake proceeds, and the failure is recorded in the {@link Status}.
+         */
+        SOFT_FAIL
+    }
+
+    /**
+     * <p>The outcome of the revocation check of a peer certificate chain,
+     * stored in the TLS session under the {@link #STATUS_ATTRIBUTE} name.</p>
+     */
+    public static class Status
+    {
+        private final FailurePolicy _failurePolicy;
+        private final boolean _stapled;
+        private final List<CertPathValidatorException> _softFailures;
+
+        public Status(FailurePolicy failurePolicy, boolean stapled, List<CertPathValidatorException> softFailures)
+        {
+            _failurePolicy = failurePolicy;
+            _stapled = stapled;
+            _softFailures = softFailures == null ? List.of() : List.copyOf(softFailures);
+        }
+
+        /**
+         * @return the policy applied during the TLS handshake
+         */
+        public FailurePolicy getFailurePolicy()
+        {
+            return _failurePolicy;
+        }
+
+        /**
+         * @return whether the peer stapled an OCSP response for its certificate
+         */
+        public boolean isStapled()
+        {
+            return _stapled;
+        }
+
+        /**
+         * @return whether the revocation status of some certificate could not be determined
+         */
+        public boolean isSoftFailed()
+        {
+            return !_softFailures.isEmpty();
+        }
+
+        /**
+         * @return the reasons why the revocation status of some certificate could not be determined
+         */
+        public List<CertPathValidatorException> getSoftFailures()
+        {
+            return _softFailures;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[policy=%s,stapled=%b,softFailures=%d]", getClass().getSimpleName(), hashCode(), _failurePolicy, _stapled, _softFailures.size());
+        }
+    }
+
+    private class RevocationTrustManager extends SslContextFactory.X509ExtendedTrustManagerWrapper
+    {
+        private final Map<X500Principal, X509Certificate> _anchors;
+
+        private RevocationTrustManager(X509ExtendedTrustManager trustManager, Map<X500Principal, X509Certificate> anchors)
+        {
+            super(trustManager);
+            _anchors = anchors;
+        }
+
+        @Override
+        public void checkClientTrusted(X509Certificate[] chain, String authType) throws CertificateException
+        {
+            super.checkClientTrusted(chain, authType);
+            check(chain, null, false);
+        }
+
+        @Override
+        public void checkClientTrusted(X509Certificate[] chain, String authType, Socket socket) throws CertificateException
+        {
+            super.checkClientTrusted(chain, authType, socket);
+            check(chain, getHandshakeSession(socket), false);
+        }
+
+        @Override
+        public void checkClientTrusted(X509Certificate[] chain, String authType, SSLEngine engine) throws CertificateException
+        {
+            super.checkClientTrusted(chain, authType, engine);
+            check(chain, engine == null ? null : engine.getHandshakeSession(), false);
+        }
+
+        @Override
+        public void checkServerTrusted(X509Certificate[] chain, String authType) throws CertificateException
+        {
+            super.checkServerTrusted(chain, authType);
+            check(chain, null, true);
+        }
+
+        @Override
+        public void checkServerTrusted(X509Certificate[] chain, String authType, Socket socket) throws CertificateException
+        {
+            super.checkServerTrusted(chain, authType, socket);
+            check(chain, getHandshakeSession(socket), true);
+        }
+
+        @Override
+        public void checkServerTrusted(X509Certificate[] chain, String authType, SSLEngine engine) throws CertificateException
+        {
+            super.checkServerTrusted(chain, authType, engine);
+            check(chain, engine == null ? null : engine.getHandshakeSession(), true);
+        }
+
+        private SSLSession getHandshakeSession(Socket socket)
+        {
+            return socket instanceof SSLSocket ? ((SSLSocket)socket).getHandshakeSession() : null;
+        }
+
+        private void check(X509Certificate[] chain, SSLSession session, boolean server) throws CertificateException
+        {
+            if (chain == null || chain.length == 0)
+                throw new CertificateException("No peer certificates");
+
+            _checks.increment();
+
+            List<byte[]> stapled = session instanceof ExtendedSSLSession ? ((ExtendedSSLSession)session).getStatusResponses() : List.of();
+            boolean hasStapled = !stapled.isEmpty() && stapled.get(0).length > 0;
+            if (server && isEnforceMustStaple() && !hasStapled && isMustStaple(chain[0]))
+            {
+                _rejections.increment();
+                throw new CertificateException("No stapled OCSP response for must-staple certificate " + chain[0].getSubjectX500Principal());
+            }
+
+            FailurePolicy policy = getFailurePolicy(session, chain);
+
+            // The certification path must not contain the trust anchor.
+            int length = chain.length;
+            for (int i = 0; i < chain.length; ++i)
+            {
+                if (chain[i].equals(_anchors.get(chain[i].getSubjectX500Principal())))
+                {
+                    length = i;
+                    break;
+                }
+            }
+
+            Status status;
+            if (length == 0)
+            {
+                // The peer certificate is itself a trust anchor.
+                status = new Status(policy, hasStapled, null);
+            }
+            else
+            {
+                List<X509Certificate> path = Arrays.asList(chain).subList(0, length);
+                X509Certificate anchor = _anchors.get(path.get(length - 1).getIssuerX500Principal());
+                if (anchor == null)
+                {
+                    CertPathValidatorException failure = new CertPathValidatorException("No trust anchor for " + path.get(length - 1).getIssuerX500Principal());
+                    status = softFail(policy, hasStapled, failure);
+                }
+                else
+                {
+                    status = check(path, anchor, stapled, policy, hasStapled);
+                }
+            }
+
+            if (LOG.isDebugEnabled())
+                LOG.debug("Revocation {} for {}", status, chain[0].getSubjectX500Principal());
+            if (status.isSoftFailed())
+                _softFailures.increment();
+            if (session != null)
+                session.putValue(STATUS_ATTRIBUTE, status);
+        }
+
+        private Status check(List<X509Certificate> path, X509Certificate anchor, List<byte[]> stapled, FailurePolicy policy, boolean hasStapled) throws CertificateException
+        {
+            boolean useCache = true;
+            while (true)
+            {
+                Set<X509Certificate> cached = new HashSet<>();
+                Map<X509Certificate, byte[]> responses = new HashMap<>();
+                for (int i = 0; i < path.size(); ++i)
+                {
+                    X509Certificate certificate = path.get(i);
+                    byte[] response = i < stapled.size() ? stapled.get(i) : null;
+                    if (response == null || response.length == 0)
+                    {
+                        if (!isEnableOCSP() || isPreferCrls() || (isOnlyEndEntity() && i > 0))
+                            continue;
+                        X509Certificate issuer = i + 1 < path.size() ? path.get(i + 1) : anchor;
+                        response = getOcspResponse(certificate, issuer, useCache, cached);
+                    }
+                    if (response != null)
+                        responses.put(certificate, response);
+                }
+
+                try
+                {
+                    return new Status(policy, hasStapled, validate(path, anchor, responses, policy));
+                }
+                catch (CertPathValidatorException x)
+                {
+                    if (x.getReason() == CertPathValidatorException.BasicReason.REVOKED)
+                    {
+                        _rejections.increment();
+                        throw new CertificateException("Revoked certificate " + path.get(Math.max(0, x.getIndex())).getSubjectX500Principal(), x);
+                    }
+                    if (!cached.isEmpty())
+                    {
+                        // Stale cached responses, query the OCSP responders again.
+                        cached.forEach(_ocspCache::remove);
+                        useCache = false;
+                        continue;
+                    }
+                    _rejections.increment();
+                    throw new CertificateException("Unable to determine revocation status", x);
+                }
+                catch (GeneralSecurityException x)
+                {
+                    _rejections.increment();
+                    throw new CertificateException(x);
+                }
+            }
+        }
+
+        private Status softFail(FailurePolicy policy, boolean hasStapled, CertPathValidatorException failure) throws CertificateException
+        {
+            if (policy == FailurePolicy.SOFT_FAIL)
+                return new Status(policy, hasStapled, List.of(failure));
+            _rejections.increment();
+            throw new CertificateException(failure.getMessage(), failure);
+        }
+    }
+
+    private static class CachedResponse
+    {
+        private final byte[] _response;
+        private final long _expireNanoTime;
+
+        private CachedResponse(byte[] response, long expireNanoTime)
+        {
+            _response = response;
+            _expireNanoTime = expireNanoTime;
+        }
+
+        private boolean isExpired()
+        {
+            return NanoTime.isBefore(_expireNanoTime, NanoTime.now());
+        }
+    }
+
+    /**
+     * <p>A minimal DER reader, sufficient to decode the certificate extensions of interest.</p>
+     */
+    private static class Der
+    {
+        private final int _tag;
+        private final byte[] _bytes;
+        private final int _offset;
+        private final int _length;
+
+        private Der(int tag, byte[] bytes, int offset, int length)
+        {
+            _tag = tag;
+            _bytes = bytes;
+            _offset = offset;
+            _length = length;
+        }
+
+        private static Der parse(byte[] bytes)
+        {
+            return parse(bytes, 0, bytes.length);
+        }
+
+        private static Der parse(byte[] bytes, int position, int limit)
+        {
+            if (limit - position < 2)
+                throw new IllegalArgumentException("Truncated DER element");
+            int tag = bytes[position++] & 0xFF;
+            int length = bytes[position++] & 0xFF;
+            if (length >= 0x80)
+            {
+                int count = length & 0x7F;
+                if (count == 0 || count > 3 || limit - position < count)
+                    throw new IllegalArgumentException("Invalid DER length");
+                length = 0;
+                for (int i = 0; i < count; ++i)
+                {
+                    length = (length << 8) | (bytes[position++] & 0xFF);
+                }
+            }
+            if (length > limit - position)
+                throw new IllegalArgumentException("Truncated DER element");
+            return new Der(tag, bytes, position, length);
+        }
+
+        private List<Der> children()
+        {
+            List<Der> children = new ArrayList<>();
+            int position = _offset;
+            int limit = _offset + _length;
+            while (position < limit)
+            {
+                Der child = parse(_bytes, position, limit);
+                children.add(child);
+                position = child._offset + child._length;
+            }
+            return children;
+        }
+
+        private byte[] value()
+        {
+            return Arrays.copyOfRange(_bytes, _offset, _offset + _length);
+        }
+    }
+}
@@ -15,11 +15,15 @@ package org.eclipse.jetty.util.ssl;
 
 import java.io.ByteArrayInputStream;
 import java.io.IOException;
+import java.lang.reflect.Method;
 import java.net.InetAddress;
 import java.net.InetSocketAddress;
 import java.net.Socket;
 import java.nio.charset.StandardCharsets;
+import java.nio.file.Path;
+import java.nio.file.Paths;
 import java.security.InvalidAlgorithmParameterException;
+import java.security.Key;
 import java.security.KeyStore;
 import java.security.NoSuchAlgorithmException;
 import java.security.Principal;
@@ -42,12 +46,14 @@ import java.util.Arrays;
 import java.util.Collection;
 import java.util.Collections;
 import java.util.Comparator;
+import java.util.EventListener;
 import java.util.HashMap;
 import java.util.LinkedHashSet;
 import java.util.List;
 import java.util.Map;
 import java.util.Objects;
 import java.util.Set;
+import java.util.concurrent.atomic.LongAdder;
 import java.util.function.Consumer;
 import java.util.regex.Pattern;
 import java.util.stream.Collectors;
@@ -75,11 +81,15 @@ import javax.net.ssl.X509ExtendedKeyManager;
 import javax.net.ssl.X509ExtendedTrustManager;
 import javax.net.ssl.X509TrustManager;
 
+import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.component.AbstractLifeCycle;
 import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
 import org.eclipse.jetty.util.resource.Resource;
 import org.eclipse.jetty.util.security.CertificateUtils;
 import org.eclipse.jetty.util.security.CertificateValidator;
@@ -95,7 +105,7 @@ import org.slf4j.LoggerFactory;
  * and {@link Client} to configure HTTP or WebSocket clients.</p>
  */
 @ManagedObject
-public abstract class SslContextFactory extends AbstractLifeCycle implements Dumpable
+public abstract class SslContextFactory extends AbstractLifeCycle implements Dumpable, Validatable
 {
     public static final TrustManager[] TRUST_ALL_CERTS = new X509TrustManager[]{new X509ExtendedTrustManagerWrapper(null)};
     public static final String DEFAULT_KEYMANAGERFACTORY_ALGORITHM = KeyManagerFactory.getDefaultAlgorithm();
@@ -108,9 +118,16 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
      * String name of keystore password property.
      */
     public static final String PASSWORD_PROPERTY = "org.eclipse.jetty.ssl.password";
+    /**
+     * String name of the boolean property that must be {@code true} for TLS key logging to be enabled.
+     *
+     * @see #setKeyLogger(SslKeyLogger)
+     */
+    public static final String KEY_LOG_PROPERTY = "org.eclipse.jetty.ssl.keyLog";
 
     private static final Logger LOG = LoggerFactory.getLogger(SslContextFactory.class);
     private static final Logger LOG_CONFIG = LoggerFactory.getLogger(LOG.getName() + ".config");
+    private static final String KEY_LOGGED_ATTRIBUTE = SslContextFactory.class.getName() + ".keyLogged";
     /**
      * Default Excluded Protocols List
      */
@@ -167,6 +184,7 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
     private boolean _enableOCSP = false;
     private String _ocspResponderURL;
     private KeyStore _setKeyStore;
+    private KeyStoreProvider _keyStoreSource;
     private KeyStore _setTrustStore;
     private boolean _sessionCachingEnabled = true;
     private int _sslSessionCacheSize = -1;
@@ -178,7 +196,14 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
     private int _renegotiationLimit = 5;
     private Factory _factory;
     private PKIXCertPathChecker _pkixCertPathChecker;
+    private RevocationChecker _revocationChecker;
     private HostnameVerifier _hostnameVerifier;
+    private long _reloads;
+    private SslKeyLogger _keyLogger;
+    private Path _keyLogFile;
+    private volatile SslKeyLogger _activeKeyLogger;
+    private Method _masterSecretMethod;
+    private final LongAdder _keyLogSkipped = new LongAdder();
 
     /**
      * Construct an instance of SslContextFactory with the default configuration.
@@ -208,11 +233,13 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
     protected void doStart() throws Exception
     {
         super.doStart();
+        LifeCycle.start(_revocationChecker);
         try (AutoLock l = _lock.lock())
         {
             load();
         }
         checkConfiguration();
+        startKeyLog();
     }
 
     protected void checkConfiguration()
@@ -266,11 +293,16 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         SSLContext context = _setContext;
         KeyStore keyStore = _setKeyStore;
         KeyStore trustStore = _setTrustStore;
+        // The current configuration is only replaced once the new one has been
+        // successfully loaded, so that a failed reload keeps the old one.
+        Map<String, X509> aliasX509 = new HashMap<>();
+        Map<String, X509> certHosts = new HashMap<>();
+        Map<String, X509> certWilds = new HashMap<>();
 
         if (context == null)
         {
             // Is this an empty factory?
-            if (keyStore == null && _keyStoreResource == null && trustStore == null && _trustStoreResource == null)
+            if (keyStore == null && _keyStoreResource == null && _keyStoreSource == null && trustStore == null && _trustStoreResource == null)
             {
                 TrustManager[] trustManagers = null;
 
@@ -287,6 +319,8 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
             }
             else
             {
+                if (keyStore == null && _keyStoreSource != null)
+                    keyStore = _keyStoreSource.getKeyStore(this);
                 if (keyStore == null)
                     keyStore = loadKeyStore(_keyStoreResource);
                 if (trustStore == null)
@@ -312,7 +346,7 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
                                 continue;
                             }
                             X509 x509 = new X509(alias, x509C);
-                            _aliasX509.put(alias, x509);
+                            aliasX509.put(alias, x509);
 
                             if (isValidateCerts())
                             {
@@ -328,11 +362,11 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
 
                             for (String h : x509.getHosts())
                             {
-                                _certHosts.put(h, x509);
+                                certHosts.put(h, x509);
                             }
                             for (String w : x509.getWilds())
                             {
-                                _certWilds.put(w, x509);
+                                certWilds.put(w, x509);
                             }
                         }
                     }
@@ -361,9 +395,26 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         // select the protocols and ciphers
         SSLParameters enabled = context.getDefaultSSLParameters();
         SSLParameters supported = context.getSupportedSSLParameters();
-        selectCipherSuites(enabled.getCipherSuites(), supported.getCipherSuites());
-        selectProtocols(enabled.getProtocols(), supported.getProtocols());
+        String[] selectedProtocols = _selectedProtocols;
+        String[] selectedCipherSuites = _selectedCipherSuites;
+        try
+        {
+            selectCipherSuites(enabled.getCipherSuites(), supported.getCipherSuites());
+            selectProtocols(enabled.getProtocols(), supported.getProtocols());
+        }
+        catch (Throwable x)
+        {
+            _selectedProtocols = selectedProtocols;
+            _selectedCipherSuites = selectedCipherSuites;
+            throw x;
+        }
 
+        _aliasX509.clear();
+        _aliasX509.putAll(aliasX509);
+        _certHosts.clear();
+        _certHosts.putAll(certHosts);
+        _certWilds.clear();
+        _certWilds.putAll(certWilds);
         _factory = new Factory(keyStore, trustStore, context);
         if (LOG.isDebugEnabled())
         {
@@ -429,13 +480,64 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         return selections;
     }
 
+    private void startKeyLog()
+    {
+        if (_keyLogger == null && _keyLogFile == null)
+            return;
+        if (!Boolean.getBoolean(KEY_LOG_PROPERTY))
+        {
+            LOG_CONFIG.warn("TLS key logging configured but ignored, system property {} is not true for {}", KEY_LOG_PROPERTY, this);
+            return;
+        }
+        _masterSecretMethod = findMasterSecretMethod();
+        if (Arrays.asList(getSelectedProtocols()).contains("TLSv1.3"))
+            LOG_CONFIG.warn("TLS key logging does not log TLSv1.3 sessions, exclude protocol TLSv1.3 to log all sessions of {}", this);
+        if (this instanceof Server && !"false".equals(System.getProperty("jdk.tls.server.enableSessionTicketExtension")))
+            LOG_CONFIG.warn("TLS key logging does not log sessions with stateless session tickets, set system property jdk.tls.server.enableSessionTicketExtension=false to log all sessions of {}", this);
+
+        SslKeyLogger keyLogger = _keyLogger != null ? _keyLogger : new SslKeyLogger.File(_keyLogFile);
+        LOG_CONFIG.warn("TLS key logging enabled to {} for {}, captured traffic can be decrypted", keyLogger, this);
+        _keyLogSkipped.reset();
+        _activeKeyLogger = keyLogger;
+    }
+
+    private Method findMasterSecretMethod()
+    {
+        String provider = _factory._context.getProvider().getName();
+        if (!"SunJSSE".equals(provider))
+            throw new IllegalStateException("TLS key logging is only supported by the SunJSSE provider, not " + provider + " for " + this);
+        try
+        {
+            Method method = Class.forName("sun.security.ssl.SSLSessionImpl").getDeclaredMethod("getMasterSecret");
+            method.setAccessible(true);
+            return method;
+        }
+        catch (Throwable x)
+        {
+            throw new IllegalStateException("TLS key logging requires JVM option --add-opens java.base/sun.security.ssl=ALL-UNNAMED " +
+                "(or =org.eclipse.jetty.util on the module path) for " + this, x);
+        }
+    }
+
+    private void stopKeyLog()
+    {
+        SslKeyLogger keyLogger = _activeKeyLogger;
+        _activeKeyLogger = null;
+        _masterSecretMethod = null;
+        // Only close the key logger created by this factory.
+        if (keyLogger instanceof SslKeyLogger.File && keyLogger != _keyLogger)
+            IO.close((SslKeyLogger.File)keyLogger);
+    }
+
     @Override
     protected void doStop() throws Exception
     {
+        stopKeyLog();
         try (AutoLock l = _lock.lock())
         {
             unload();
         }
+        LifeCycle.stop(_revocationChecker);
         super.doStop();
     }
 
@@ -449,6 +551,52 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         _certWilds.clear();
     }
 
+    /**
+     * <p>Loads the key and trust stores, if not already loaded, and checks that
+     * the certificates of the key store are currently valid.</p>
+     *
+     * @param report the report to add the problems to
+     */
+    @Override
+    public void validate(ValidationReport report)
+    {
+        boolean started = isStarted();
+        try
+        {
+            if (!started)
+                start();
+            for (String alias : getAliases())
+            {
+                try
+                {
+                    getX509(alias).getCertificate().checkValidity();
+                }
+                catch (CertificateException x)
+                {
+                    report.addProblem(this, String.format("Certificate %s in %s: %s", alias, getKeyStorePath(), x.getMessage()), x);
+                }
+            }
+        }
+        catch (Throwable x)
+        {
+            report.addProblem(this, x);
+        }
+        finally
+        {
+            if (!started)
+            {
+                try
+                {
+                    stop();
+                }
+                catch (Throwable x)
+                {
+                    LOG.trace("IGNORED", x);
+                }
+            }
+        }
+    }
+
     Map<String, X509> aliasCerts()
     {
         return _aliasX509;
@@ -498,6 +646,53 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         return _aliasX509.get(alias);
     }
 
+    /**
+     * @return a description of the active certificates, with their serial number and expiration
+     */
+    @ManagedAttribute(value = "The active certificates", readonly = true)
+    public String[] getActiveCertificates()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _aliasX509.values().stream()
+                .map(x509 -> String.format("%s: serial=%s, subject=%s, notAfter=%s",
+                    x509.getAlias(),
+                    x509.getCertificate().getSerialNumber().toString(16),
+                    x509.getCertificate().getSubjectX500Principal().getName(),
+                    x509.getCertificate().getNotAfter().toInstant()))
+                .sorted()
+                .toArray(String[]::new);
+        }
+    }
+
+    /**
+     * @return the earliest expiration time of the active certificates,
+     * in milliseconds since the epoch, or -1 if there are no active certificates
+     */
+    @ManagedAttribute(value = "The earliest expiration time of the active certificates, in epoch milliseconds", readonly = true)
+    public long getActiveCertificatesNotAfter()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _aliasX509.values().stream()
+                .mapToLong(x509 -> x509.getCertificate().getNotAfter().getTime())
+                .min()
+                .orElse(-1);
+        }
+    }
+
+    /**
+     * @return the number of times this SslContextFactory has been successfully reloaded
+     */
+    @ManagedAttribute(value = "The number of successful reloads", readonly = true)
+    public long getReloadCount()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            return _reloads;
+        }
+    }
+
     /**
      * @return The array of protocol names to exclude from
      * {@link SSLEngine#setEnabledProtocols(String[])}
@@ -1108,6 +1303,27 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         _pkixCertPathChecker = pkixCertPatchChecker;
     }
 
+    /**
+     * @return the checker of the revocation status of peer certificates, or {@code null}
+     */
+    public RevocationChecker getRevocationChecker()
+    {
+        return _revocationChecker;
+    }
+
+    /**
+     * <p>Sets the checker of the revocation status of peer certificates.</p>
+     * <p>The checker is applied to the trust managers created from the trust store,
+     * independently of {@link #setValidatePeerCerts(boolean)}, and its lifecycle
+     * is bound to the lifecycle of this SslContextFactory.</p>
+     *
+     * @param revocationChecker the checker of the revocation status of peer certificates, or {@code null}
+     */
+    public void setRevocationChecker(RevocationChecker revocationChecker)
+    {
+        _revocationChecker = revocationChecker;
+    }
+
     /**
      * Override this method to provide alternate way to load a keystore.
      *
@@ -1209,6 +1425,15 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
 
                 managers = trustManagerFactory.getTrustManagers();
             }
+
+            if (_revocationChecker != null)
+            {
+                for (int i = 0; i < managers.length; ++i)
+                {
+                    if (managers[i] instanceof X509ExtendedTrustManager)
+                        managers[i] = _revocationChecker.newTrustManager((X509ExtendedTrustManager)managers[i], trustStore);
+                }
+            }
         }
 
         return managers;
@@ -1429,6 +1654,24 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         _setKeyStore = keyStore;
     }
 
+    /**
+     * <p>Sets a programmatic source of the key store, invoked every time
+     * this SslContextFactory is started or reloaded.</p>
+     * <p>The source is only used if a key store has not been
+     * {@link #setKeyStore(KeyStore) explicitly set}.</p>
+     *
+     * @param keyStoreSource the key store source
+     */
+    public void setKeyStoreSource(KeyStoreProvider keyStoreSource)
+    {
+        _keyStoreSource = keyStoreSource;
+    }
+
+    public KeyStoreProvider getKeyStoreSource()
+    {
+        return _keyStoreSource;
+    }
+
     public KeyStore getKeyStore()
     {
         if (!isStarted())
@@ -1593,6 +1836,176 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         _hostnameVerifier = hostnameVerifier;
     }
 
+    /**
+     * @return the consumer of TLS key material, or null if not configured
+     * @see #setKeyLogger(SslKeyLogger)
+     */
+    public SslKeyLogger getKeyLogger()
+    {
+        return _keyLogger;
+    }
+
+    /**
+     * <p>Sets the consumer of TLS key material, in NSS key log format.</p>
+     * <p>TLS key logging is a debugging aid that allows captured traffic to be decrypted,
+     * for example by Wireshark; it is only enabled if the system property
+     * {@value #KEY_LOG_PROPERTY} is {@code true} when this factory is started.</p>
+     * <p>JSSE offers no supported mechanism to export TLS secrets, so key logging
+     * reads them from the JDK internal TLS implementation, with these limits:</p>
+     * <ul>
+     * <li>only the SunJSSE provider is supported;</li>
+     * <li>the JVM must be run with {@code --add-opens java.base/sun.security.ssl=ALL-UNNAMED}
+     * (or {@code =org.eclipse.jetty.util} on the module path), which is required
+     * since JDK 16 and avoids an illegal access warning on earlier JDKs;</li>
+     * <li>TLS 1.3 sessions are not logged, since JSSE does not retain their traffic secrets;</li>
+     * <li>TLS 1.2 sessions with stateless session tickets have no session ID and are not
+     * logged, which on the server can be avoided with the system property
+     * {@code jdk.tls.server.enableSessionTicketExtension=false}.</li>
+     * </ul>
+     * <p>This factory fails to start if the first two conditions are not met, and warns
+     * at start about the others; the sessions that cannot be logged are
+     * {@link #getKeyLogSkippedCount() counted}.</p>
+     * <p>When both a key logger and a {@link #setKeyLogFile(Path) key log file} are
+     * configured, the key logger takes precedence.</p>
+     *
+     * @param keyLogger the consumer of TLS key material
+     * @see #logKeys(SSLSession)
+     */
+    public void setKeyLogger(SslKeyLogger keyLogger)
+    {
+        _keyLogger = keyLogger;
+    }
+
+    /**
+     * @return the file where TLS key material is appended, or null if not configured
+     * @see #setKeyLogFile(Path)
+     */
+    @ManagedAttribute("The TLS key log file")
+    public Path getKeyLogFile()
+    {
+        return _keyLogFile;
+    }
+
+    /**
+     * <p>Sets the file where TLS key material is appended, in NSS key log format,
+     * typically the same file named by the {@code SSLKEYLOGFILE} environment variable.</p>
+     * <p>Like {@link #setKeyLogger(SslKeyLogger)}, key logging is only enabled if the
+     * system property {@value #KEY_LOG_PROPERTY} is {@code true}.</p>
+     *
+     * @param keyLogFile the file where TLS key material is appended
+     */
+    public void setKeyLogFile(Path keyLogFile)
+    {
+        _keyLogFile = keyLogFile;
+    }
+
+    /**
+     * @return whether TLS key logging is enabled for this started factory
+     */
+    @ManagedAttribute("Whether TLS key logging is enabled")
+    public boolean isKeyLogEnabled()
+    {
+        return _activeKeyLogger != null;
+    }
+
+    /**
+     * @return the number of TLS sessions whose key material could not be logged
+     * @see #setKeyLogger(SslKeyLogger)
+     */
+    @ManagedAttribute("The number of TLS sessions whose keys could not be logged")
+    public long getKeyLogSkippedCount()
+    {
+        return _keyLogSkipped.sum();
+    }
+
+    /**
+     * <p>Returns the key log file when TLS key logging is enabled.</p>
+     * <p>This is meant for TLS implementations that write the key material themselves,
+     * such as QUIC implementations.</p>
+     *
+     * @return the key log file if key logging is enabled, or null
+     */
+    public Path getEnabledKeyLogFile()
+    {
+        SslKeyLogger keyLogger = _activeKeyLogger;
+        if (keyLogger instanceof SslKeyLogger.File)
+            return ((SslKeyLogger.File)keyLogger).getPath();
+        return null;
+    }
+
+    /**
+     * <p>Logs the key material of the given TLS session, if TLS key logging is enabled.</p>
+     * <p>This method is typically called when a TLS handshake succeeds, but may also be
+     * called explicitly, for example when processing a request over a TLS connection.
+     * The key material of a session is only logged once.</p>
+     * <p>Only TLS 1.2 (and earlier) sessions with a session ID can be logged,
+     * see {@link #setKeyLogger(SslKeyLogger)} for the limits of key logging.</p>
+     *
+     * @param session the TLS session to log the key material of
+     */
+    public void logKeys(SSLSession session)
+    {
+        SslKeyLogger keyLogger = _activeKeyLogger;
+        if (keyLogger == null || session == null)
+            return;
+        if (session.getValue(KEY_LOGGED_ATTRIBUTE) != null)
+            return;
+
+        session.putValue(KEY_LOGGED_ATTRIBUTE, Boolean.TRUE);
+
+        if ("TLSv1.3".equals(session.getProtocol()))
+        {
+            skipKeyLog(session, "TLSv1.3 secrets are not available", null);
+            return;
+        }
+        byte[] sessionId = session.getId();
+        if (sessionId == null || sessionId.length == 0)
+        {
+            skipKeyLog(session, "no session ID", null);
+            return;
+        }
+        byte[] masterSecret = getMasterSecret(session);
+        if (masterSecret == null)
+            return;
+
+        String line = String.format("RSA Session-ID:%s Master-Key:%s",
+            StringUtil.asciiToLowerCase(StringUtil.toHexString(sessionId)),
+            StringUtil.asciiToLowerCase(StringUtil.toHexString(masterSecret)));
+        if (LOG.isDebugEnabled())
+            LOG.debug("Logging TLS keys for session {}", session);
+        keyLogger.log(line);
+    }
+
+    private byte[] getMasterSecret(SSLSession session)
+    {
+        Method method = _masterSecretMethod;
+        if (method == null || !method.getDeclaringClass().isInstance(session))
+        {
+            skipKeyLog(session, "unsupported session " + session.getClass().getName(), null);
+            return null;
+        }
+        try
+        {
+            Key key = (Key)method.invoke(session);
+            if (key != null && key.getEncoded() != null)
+                return key.getEncoded();
+            skipKeyLog(session, "no master secret", null);
+            return null;
+        }
+        catch (Throwable x)
+        {
+            skipKeyLog(session, "master secret not accessible", x);
+            return null;
+        }
+    }
+
+    private void skipKeyLog(SSLSession session, String reason, Throwable cause)
+    {
+        _keyLogSkipped.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Not logging TLS keys for session {}: {}", session, reason, cause);
+    }
+
     /**
      * Returns the password object for the given realm.
      *
@@ -1892,13 +2305,69 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         return sslParams;
     }
 
+    /**
+     * <p>Reloads this SslContextFactory, typically to replace the certificates.</p>
+     * <p>Only TLS handshakes started after the reload use the new configuration.
+     * If the reload fails, for example because the new key store cannot be loaded,
+     * the previous key store, trust store and {@link SSLContext} remain in use.
+     * {@link Listener}s added via {@link #addEventListener(EventListener)} are
+     * notified of the reload outcome.</p>
+     *
+     * @param consumer a consumer of this SslContextFactory that may change its configuration
+     * @throws Exception if the reload fails
+     */
     public void reload(Consumer<SslContextFactory> consumer) throws Exception
     {
-        try (AutoLock l = _lock.lock())
+        try
         {
-            consumer.accept(this);
-            unload();
-            load();
+            try (AutoLock l = _lock.lock())
+            {
+                consumer.accept(this);
+                load();
+                ++_reloads;
+            }
+        }
+        catch (Throwable x)
+        {
+            notifyReloadFailure(x);
+            throw x;
+        }
+        notifyReloaded();
+    }
+
+    private void notifyReloaded()
+    {
+        for (EventListener listener : getEventListeners())
+        {
+            if (listener instanceof Listener)
+            {
+                try
+                {
+                    ((Listener)listener).onReloaded(this);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", listener, x);
+                }
+            }
+        }
+    }
+
+    private void notifyReloadFailure(Throwable failure)
+    {
+        for (EventListener listener : getEventListeners())
+        {
+            if (listener instanceof Listener)
+            {
+                try
+                {
+                    ((Listener)listener).onReloadFailure(this, failure);
+                }
+                catch (Throwable x)
+                {
+                    LOG.info("Failure while notifying listener {}", listener, x);
+                }
+            }
         }
     }
 
@@ -2038,6 +2507,33 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
             _trustStoreResource);
     }
 
+    /**
+     * <p>A listener for {@link SslContextFactory} reload events.</p>
+     *
+     * @see #reload(Consumer)
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when the {@link SslContextFactory} has been reloaded.</p>
+         *
+         * @param sslContextFactory the reloaded {@link SslContextFactory}
+         */
+        default void onReloaded(SslContextFactory sslContextFactory)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the {@link SslContextFactory} reload failed.</p>
+         *
+         * @param sslContextFactory the {@link SslContextFactory} that failed to reload
+         * @param failure the reload failure
+         */
+        default void onReloadFailure(SslContextFactory sslContextFactory, Throwable failure)
+        {
+        }
+    }
+
     private static class Factory
     {
         private final KeyStore _keyStore;
@@ -2094,6 +2590,7 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
     public static class Client extends SslContextFactory
     {
         private SniProvider sniProvider = (sslEngine, serverNames) -> serverNames;
+        private boolean echGrease;
 
         public Client()
         {
@@ -2145,6 +2642,28 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
             this.sniProvider = Objects.requireNonNull(sniProvider);
         }
 
+        /**
+         * @return whether a GREASE Encrypted Client Hello extension is sent
+         * when no ECH configuration is available for the server
+         */
+        public boolean isECHGrease()
+        {
+            return echGrease;
+        }
+
+        /**
+         * <p>Sets whether a GREASE Encrypted Client Hello extension is sent when no ECH
+         * configuration is available for the server, so that connections that use ECH
+         * cannot be distinguished from connections that do not use it.</p>
+         * <p>ECH requires support from the TLS provider, otherwise this setting is ignored.</p>
+         *
+         * @param echGrease whether a GREASE ECH extension is sent
+         */
+        public void setECHGrease(boolean echGrease)
+        {
+            this.echGrease = echGrease;
+        }
+
         /**
          * <p>A provider for SNI names to send to the server during the TLS handshake.</p>
          * <p>By default, the OpenJDK TLS implementation does not send SNI names when
@@ -2207,12 +2726,81 @@ public abstract class SslContextFactory extends AbstractLifeCycle implements Dum
         private boolean _wantClientAuth;
         private boolean _sniRequired;
         private SniX509ExtendedKeyManager.SniSelector _sniSelector;
+        private String _echKeyPath;
+        private List<ECHKey> _echKeys = List.of();
+        private List<ECHKey> _loadedECHKeys = List.of();
 
         public Server()
         {
             setEndpointIdentificationAlgorithm(null);
         }
 
+        @Override
+        protected void doStart() throws Exception
+        {
+            super.doStart();
+            if (_echKeyPath != null)
+            {
+                _loadedECHKeys = ECHKey.load(Paths.get(_echKeyPath));
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Loaded ECH keys {} from {}", _loadedECHKeys, _echKeyPath);
+            }
+        }
+
+        @Override
+        protected void doStop() throws Exception
+        {
+            _loadedECHKeys = List.of();
+            super.doStop();
+        }
+
+        /**
+         * @return the path of the PEM file with the Encrypted Client Hello keys
+         */
+        @ManagedAttribute("The path of the PEM file with the Encrypted Client Hello keys")
+        public String getECHKeyPath()
+        {
+            return _echKeyPath;
+        }
+
+        /**
+         * <p>Sets the path of the PEM file with the Encrypted Client Hello keys,
+         * in the format described by {@link ECHKey#load(Path)}.</p>
+         * <p>The ECH configurations of the keys must be published by the DNS {@code HTTPS}
+         * records of the server, so that clients may encrypt their ClientHello.
+         * ECH requires support from the TLS provider: if no
+         * {@code org.eclipse.jetty.io.ssl.ECHProcessor} applies to the TLS provider,
+         * the keys are ignored and the clients will fall back to the non encrypted
+         * ClientHello, after authenticating the server for the public name of the
+         * ECH configurations.</p>
+         *
+         * @param echKeyPath the path of the PEM file with the ECH keys
+         */
+        public void setECHKeyPath(String echKeyPath)
+        {
+            _echKeyPath = echKeyPath;
+        }
+
+        /**
+         * @return the Encrypted Client Hello keys, both those configured
+         * via {@link #setECHKeys(List)} and those loaded from {@link #getECHKeyPath()}
+         */
+        public List<ECHKey> getECHKeys()
+        {
+            List<ECHKey> result = new ArrayList<>(_echKeys);
+            result.addAll(_loadedECHKeys);
+            return result;
+        }
+
+        /**
+         * @param echKeys the Encrypted Client Hello keys
+         * @see #setECHKeyPath(String)
+         */
+        public void setECHKeys(List<ECHKey> echKeys)
+        {
+            _echKeys = List.copyOf(echKeys);
+        }
+
         /**
          * @return True if SSL needs client authentication.
          * @see SSLEngine#getNeedClientAuth()
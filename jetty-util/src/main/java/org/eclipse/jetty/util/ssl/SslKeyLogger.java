@@ -0,0 +1,124 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.Closeable;
+import java.io.IOException;
+import java.io.Writer;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.FileAlreadyExistsException;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.nio.file.attribute.PosixFilePermissions;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>A consumer of TLS key material in the
+ * <a href="https://firefox-source-docs.mozilla.org/security/nss/legacy/key_log_format/index.html">NSS key log format</a>,
+ * the format of the files named by the {@code SSLKEYLOGFILE} environment variable, that tools such as
+ * Wireshark use to decrypt captured TLS traffic.</p>
+ * <p>Key logging is a debugging aid that defeats the confidentiality of TLS, and it is only enabled when
+ * both a key logger is configured with {@link SslContextFactory#setKeyLogger(SslKeyLogger)} or
+ * {@link SslContextFactory#setKeyLogFile(Path)}, and the system property
+ * {@value SslContextFactory#KEY_LOG_PROPERTY} is {@code true}.</p>
+ */
+@FunctionalInterface
+public interface SslKeyLogger
+{
+    /**
+     * @param line a line of the NSS key log format, without line terminator
+     */
+    public void log(String line);
+
+    /**
+     * <p>A {@link SslKeyLogger} that appends the key log lines to a file.</p>
+     * <p>On file systems that support POSIX permissions, the file is created,
+     * if it does not exist, readable and writable only by its owner;
+     * the permissions of an existing file are not changed.</p>
+     */
+    public static class File implements SslKeyLogger, Closeable
+    {
+        private final AutoLock _lock = new AutoLock();
+        private final Path _path;
+        private Writer _writer;
+
+        public File(Path path)
+        {
+            _path = Objects.requireNonNull(path);
+        }
+
+        /**
+         * @return the path of the key log file
+         */
+        public Path getPath()
+        {
+            return _path;
+        }
+
+        @Override
+        public void log(String line)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_writer == null)
+                {
+                    createOwnerOnly();
+                    _writer = Files.newBufferedWriter(_path, StandardCharsets.US_ASCII, StandardOpenOption.CREATE, StandardOpenOption.APPEND);
+                }
+                _writer.write(line);
+                _writer.write('\n');
+                // Flush every line, so that the file can be used while capturing.
+                _writer.flush();
+            }
+            catch (IOException x)
+            {
+                throw new IllegalStateException("Unable to write TLS key log file " + _path, x);
+            }
+        }
+
+        private void createOwnerOnly() throws IOException
+        {
+            if (!_path.getFileSystem().supportedFileAttributeViews().contains("posix"))
+                return;
+            try
+            {
+                Files.createFile(_path, PosixFilePermissions.asFileAttribute(PosixFilePermissions.fromString("rw-------")));
+            }
+            catch (FileAlreadyExistsException x)
+            {
+                // Keep the permissions of the existing file.
+            }
+        }
+
+        @Override
+        public void close() throws IOException
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (_writer != null)
+                    _writer.close();
+                _writer = null;
+            }
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), _path);
+        }
+    }
+}
@@ -0,0 +1,314 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.statistic;
+
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.AtomicLongArray;
+
+import org.eclipse.jetty.util.NanoTime;
+import org.eclipse.jetty.util.thread.AutoLock;
+
+/**
+ * <p>Statistics on the distribution of a sampled value over a sliding time window.</p>
+ * <p>Samples are counted in buckets whose width grows with the value, in the
+ * style of <a href="http://hdrhistogram.org/">HdrHistogram</a>, so that
+ * percentiles such as the median or the 99th percentile are computed with a
+ * bounded relative error (about 6%) and in constant memory, whatever the number
+ * of samples.</p>
+ * <p>The window is divided in slots: samples are recorded in the slot of the
+ * current time, and a slot is cleared when it is reused after the time of
+ * the whole window has elapsed, so that the statistics cover the last window,
+ * with the granularity of a slot.</p>
+ * <p>Recording is lock-free, except when a slot is reused; reading the statistics
+ * is not atomic with respect to concurrent recording, so the values are approximate.</p>
+ */
+public class HistogramStatistic
+{
+    // Sub-buckets per power of two, that determines the precision.
+    private static final int SUB_BUCKET_BITS = 5;
+    private static final int SUB_BUCKETS = 1 << SUB_BUCKET_BITS;
+    private static final int HALF_SUB_BUCKETS = SUB_BUCKETS / 2;
+    // Values are capped to 2^40, about 12 days in milliseconds or 18 minutes in microseconds.
+    private static final int MAX_EXPONENT = 40;
+    private static final long MAX_VALUE = (1L << MAX_EXPONENT) - 1;
+    private static final int BUCKETS = SUB_BUCKETS + (MAX_EXPONENT - SUB_BUCKET_BITS) * HALF_SUB_BUCKETS;
+
+    private final AutoLock _lock = new AutoLock();
+    private final Slot[] _slots;
+    private final long _slotNanos;
+    private final TimeUnit _units;
+    private volatile long _origin;
+
+    /**
+     * <p>Creates a histogram over the given window, divided in 6 slots.</p>
+     *
+     * @param window the duration of the window
+     * @param units the units of the window
+     */
+    public HistogramStatistic(long window, TimeUnit units)
+    {
+        this(window, units, 6);
+    }
+
+    /**
+     * @param window the duration of the window
+     * @param units the units of the window
+     * @param slots the number of slots the window is divided in
+     */
+    public HistogramStatistic(long window, TimeUnit units, int slots)
+    {
+        if (window <= 0)
+            throw new IllegalArgumentException("Invalid window " + window);
+        if (slots <= 0)
+            throw new IllegalArgumentException("Invalid slots " + slots);
+        _slotNanos = Math.max(1, units.toNanos(window) / slots);
+        _units = units;
+        _slots = new Slot[slots];
+        for (int i = 0; i < slots; i++)
+        {
+            _slots[i] = new Slot();
+        }
+        _origin = now();
+    }
+
+    /**
+     * @return the duration of the window
+     */
+    public long getWindow()
+    {
+        return _units.convert(_slotNanos * _slots.length, TimeUnit.NANOSECONDS);
+    }
+
+    /**
+     * @return the units of the window
+     */
+    public TimeUnit getUnits()
+    {
+        return _units;
+    }
+
+    /**
+     * Resets the statistics.
+     */
+    public void reset()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            for (Slot slot : _slots)
+            {
+                slot.clear(Long.MIN_VALUE);
+            }
+            _origin = now();
+        }
+    }
+
+    /**
+     * <p>Records a sample value.</p>
+     * <p>Negative values are recorded as zero, and values larger than 2<sup>40</sup>
+     * are recorded as 2<sup>40</sup>.</p>
+     *
+     * @param value the value to record
+     */
+    public void record(long value)
+    {
+        long period = period();
+        Slot slot = _slots[(int)Math.floorMod(period, (long)_slots.length)];
+        if (slot._period != period)
+        {
+            try (AutoLock l = _lock.lock())
+            {
+                if (slot._period != period)
+                    slot.clear(period);
+            }
+        }
+        long sample = Math.min(Math.max(0, value), MAX_VALUE);
+        slot._counts.incrementAndGet(index(sample));
+        slot._count.incrementAndGet();
+        slot._max.accumulateAndGet(sample, Math::max);
+    }
+
+    /**
+     * @return the number of samples recorded in the window
+     */
+    public long getCount()
+    {
+        return snapshot().getCount();
+    }
+
+    /**
+     * @return the max value of the samples recorded in the window
+     */
+    public long getMax()
+    {
+        return snapshot().getMax();
+    }
+
+    /**
+     * @param percentile the percentile, between 0 and 100
+     * @return the value below which the given percentage of the samples recorded in the window fall
+     * @see Snapshot#getPercentile(double)
+     */
+    public long getPercentile(double percentile)
+    {
+        return snapshot().getPercentile(percentile);
+    }
+
+    /**
+     * <p>Takes a snapshot of the samples recorded in the window, to compute
+     * multiple statistics over the same samples.</p>
+     *
+     * @return a snapshot of the samples recorded in the window
+     */
+    public Snapshot snapshot()
+    {
+        long period = period();
+        long[] counts = new long[BUCKETS];
+        long count = 0;
+        long max = 0;
+        for (Slot slot : _slots)
+        {
+            long elapsed = period - slot._period;
+            if (elapsed < 0 || elapsed >= _slots.length)
+                continue;
+            for (int i = 0; i < BUCKETS; i++)
+            {
+                counts[i] += slot._counts.get(i);
+            }
+            count += slot._count.get();
+            max = Math.max(max, slot._max.get());
+        }
+        return new Snapshot(counts, count, max);
+    }
+
+    /**
+     * @return the current time in nanoseconds, as returned by {@link NanoTime#now()}
+     */
+    protected long now()
+    {
+        return NanoTime.now();
+    }
+
+    private long period()
+    {
+        return NanoTime.elapsed(_origin, now()) / _slotNanos;
+    }
+
+    private static int index(long value)
+    {
+        if (value < SUB_BUCKETS)
+            return (int)value;
+        int exponent = 63 - Long.numberOfLeadingZeros(value);
+        int shift = exponent - SUB_BUCKET_BITS + 1;
+        int subBucket = (int)(value >>> shift);
+        return SUB_BUCKETS + (exponent - SUB_BUCKET_BITS) * HALF_SUB_BUCKETS + subBucket - HALF_SUB_BUCKETS;
+    }
+
+    private static long highestValue(int index)
+    {
+        if (index < SUB_BUCKETS)
+            return index;
+        int group = (index - SUB_BUCKETS) / HALF_SUB_BUCKETS;
+        int shift = group + 1;
+        long subBucket = HALF_SUB_BUCKETS + (index - SUB_BUCKETS) % HALF_SUB_BUCKETS;
+        return ((subBucket + 1) << shift) - 1;
+    }
+
+    @Override
+    public String toString()
+    {
+        Snapshot snapshot = snapshot();
+        return String.format("%s@%x{count=%d,max=%d,p50=%d,p99=%d per %d %s}",
+            getClass().getSimpleName(), hashCode(),
+            snapshot.getCount(), snapshot.getMax(), snapshot.getPercentile(50), snapshot.getPercentile(99),
+            getWindow(), _units);
+    }
+
+    /**
+     * <p>The samples recorded in the window at the time of a {@link #snapshot()}.</p>
+     */
+    public static class Snapshot
+    {
+        private final long[] _counts;
+        private final long _count;
+        private final long _max;
+
+        private Snapshot(long[] counts, long count, long max)
+        {
+            _counts = counts;
+            _count = count;
+            _max = max;
+        }
+
+        /**
+         * @return the number of samples
+         */
+        public long getCount()
+        {
+            return _count;
+        }
+
+        /**
+         * @return the max value of the samples, or zero if there are no samples
+         */
+        public long getMax()
+        {
+            return _max;
+        }
+
+        /**
+         * <p>Returns the value below which the given percentage of the samples fall.</p>
+         * <p>The value returned is the highest value of the bucket of the percentile,
+         * capped to the max value of the samples.</p>
+         *
+         * @param percentile the percentile, between 0 and 100
+         * @return the value of the given percentile, or zero if there are no samples
+         */
+        public long getPercentile(double percentile)
+        {
+            if (percentile < 0 || percentile > 100)
+                throw new IllegalArgumentException("Invalid percentile " + percentile);
+            if (_count == 0)
+                return 0;
+            long rank = Math.max(1, (long)Math.ceil(percentile / 100 * _count));
+            long total = 0;
+            for (int i = 0; i < _counts.length; i++)
+            {
+                total += _counts[i];
+                if (total >= rank)
+                    return Math.min(highestValue(i), _max);
+            }
+            return _max;
+        }
+    }
+
+    private static class Slot
+    {
+        private final AtomicLongArray _counts = new AtomicLongArray(BUCKETS);
+        private final AtomicLong _count = new AtomicLong();
+        private final AtomicLong _max = new AtomicLong();
+        private volatile long _period = Long.MIN_VALUE;
+
+        private void clear(long period)
+        {
+            for (int i = 0; i < BUCKETS; i++)
+            {
+                _counts.set(i, 0);
+            }
+            _count.set(0);
+            _max.set(0);
+            _period = period;
+        }
+    }
+}
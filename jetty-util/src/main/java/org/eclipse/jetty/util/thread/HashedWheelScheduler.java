@@ -0,0 +1,512 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.thread;
+
+import java.io.IOException;
+import java.util.Map;
+import java.util.Queue;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicIntegerFieldUpdater;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.concurrent.atomic.LongAdder;
+import java.util.concurrent.locks.LockSupport;
+
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.component.Dumpable;
+import org.eclipse.jetty.util.component.DumpableCollection;
+import org.eclipse.jetty.util.statistic.SampleStatistic;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Implementation of {@link Scheduler} based on a hashed timing wheel.</p>
+ * <p>The wheel is an array of buckets, each covering a tick of time; a single
+ * thread advances the wheel one tick at a time and runs the tasks expired in
+ * the current bucket.
+ * Tasks delayed by more than a wheel revolution stay in their bucket for
+ * multiple revolutions: they are counted as {@link #getOverflowTasks() overflow tasks}.</p>
+ * <p>Scheduling a task is a lock-free enqueue and cancelling a task is a single
+ * atomic operation; cancelled tasks are unlinked from their bucket when the wheel
+ * next visits it.
+ * This makes this scheduler suitable for very large numbers of idle timeouts,
+ * which are almost always cancelled before they expire, at the cost of a
+ * precision bounded by the tick duration.</p>
+ * <p>Tasks are run by the wheel thread, so they must be quick and must not block.
+ * Tasks run more than one tick after the end of the tick of their deadline are counted as
+ * {@link #getLateTasks() late tasks}, which typically indicates that tasks do
+ * not return quickly enough.</p>
+ * <p>Components such as connectors or clients may be given their own
+ * {@link #getComponentScheduler(String) component scheduler}, which shares this
+ * wheel but tracks timeout statistics for that component only.</p>
+ */
+@ManagedObject
+public class HashedWheelScheduler extends AbstractLifeCycle implements Scheduler, Dumpable
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HashedWheelScheduler.class);
+    private static final int MAX_TRANSFERS_PER_TICK = 100_000;
+
+    private final Map<String, ComponentScheduler> _components = new ConcurrentHashMap<>();
+    private final Queue<WheelTask> _added = new ConcurrentLinkedQueue<>();
+    private final AtomicLong _pendingTasks = new AtomicLong();
+    private final LongAdder _overflowTasks = new LongAdder();
+    private final LongAdder _lateTasks = new LongAdder();
+    private final SampleStatistic _lateness = new SampleStatistic();
+    private final String _name;
+    private final boolean _daemon;
+    private final long _tickNanos;
+    private final WheelTask[] _wheel;
+    private volatile long _startNanos;
+    private volatile Thread _thread;
+
+    public HashedWheelScheduler()
+    {
+        this(null, false);
+    }
+
+    public HashedWheelScheduler(String name, boolean daemon)
+    {
+        this(name, daemon, 10, 512);
+    }
+
+    /**
+     * @param name The name of the wheel thread or null for automatic name
+     * @param daemon True if the wheel thread should be daemon
+     * @param tickMillis The duration of a wheel tick, in milliseconds
+     * @param wheelSize The number of buckets of the wheel, rounded up to a power of 2
+     */
+    public HashedWheelScheduler(@Name("name") String name, @Name("daemon") boolean daemon, @Name("tickMillis") long tickMillis, @Name("wheelSize") int wheelSize)
+    {
+        if (tickMillis <= 0)
+            throw new IllegalArgumentException("Invalid tick duration " + tickMillis);
+        if (wheelSize <= 0 || wheelSize > 1 << 30)
+            throw new IllegalArgumentException("Invalid wheel size " + wheelSize);
+        _name = StringUtil.isBlank(name) ? "Scheduler-" + hashCode() : name;
+        _daemon = daemon;
+        _tickNanos = TimeUnit.MILLISECONDS.toNanos(tickMillis);
+        int size = Integer.highestOneBit(wheelSize);
+        _wheel = new WheelTask[size == wheelSize ? size : size << 1];
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        _startNanos = System.nanoTime();
+        Thread thread = _thread = new Thread(this::wheel, _name);
+        thread.setDaemon(_daemon);
+        thread.start();
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        Thread thread = _thread;
+        _thread = null;
+        if (thread != null)
+        {
+            thread.interrupt();
+            // The scheduler may be stopped by one of its tasks.
+            if (thread != Thread.currentThread())
+                thread.join();
+        }
+        // Pending tasks never run after stop.
+        _added.clear();
+        for (int i = 0; i < _wheel.length; ++i)
+        {
+            _wheel[i] = null;
+        }
+        _pendingTasks.set(0);
+        _components.values().forEach(ComponentScheduler::clear);
+        super.doStop();
+    }
+
+    @Override
+    public Task schedule(Runnable task, long delay, TimeUnit unit)
+    {
+        return schedule(null, task, delay, unit);
+    }
+
+    private Task schedule(ComponentScheduler component, Runnable task, long delay, TimeUnit unit)
+    {
+        if (!isRunning())
+            return () -> false;
+        long deadline = System.nanoTime() - _startNanos + unit.toNanos(Math.max(0, delay));
+        // Guard against overflow for very long delays.
+        if (deadline < 0)
+            deadline = Long.MAX_VALUE;
+        WheelTask wheelTask = new WheelTask(this, component, task, deadline);
+        _pendingTasks.incrementAndGet();
+        if (component != null)
+            component.onScheduled();
+        _added.offer(wheelTask);
+        return wheelTask;
+    }
+
+    private void wheel()
+    {
+        long tick = 0;
+        while (isRunning())
+        {
+            long now = System.nanoTime() - _startNanos;
+            long sleep = (tick + 1) * _tickNanos - now;
+            if (sleep > 0)
+            {
+                LockSupport.parkNanos(this, sleep);
+                continue;
+            }
+            transfer(tick);
+            expire((int)(tick & (_wheel.length - 1)), now);
+            ++tick;
+        }
+    }
+
+    private void transfer(long tick)
+    {
+        for (int i = 0; i < MAX_TRANSFERS_PER_TICK; ++i)
+        {
+            WheelTask task = _added.poll();
+            if (task == null)
+                return;
+            if (task.isCancelled())
+                continue;
+            long expiryTick = task._deadline / _tickNanos;
+            task._rounds = (expiryTick - tick) / _wheel.length;
+            if (task._rounds > 0)
+                _overflowTasks.increment();
+            // Tasks that should have already expired go in the current bucket.
+            int index = (int)(Math.max(expiryTick, tick) & (_wheel.length - 1));
+            task._next = _wheel[index];
+            _wheel[index] = task;
+        }
+    }
+
+    private void expire(int index, long now)
+    {
+        WheelTask previous = null;
+        WheelTask task = _wheel[index];
+        while (task != null)
+        {
+            WheelTask next = task._next;
+            boolean remove = true;
+            if (!task.isCancelled())
+            {
+                if (task._rounds <= 0)
+                {
+                    expire(task, now);
+                }
+                else
+                {
+                    --task._rounds;
+                    remove = false;
+                }
+            }
+            if (remove)
+            {
+                if (previous == null)
+                    _wheel[index] = next;
+                else
+                    previous._next = next;
+                task._next = null;
+            }
+            else
+            {
+                previous = task;
+            }
+            task = next;
+        }
+    }
+
+    private void expire(WheelTask task, long now)
+    {
+        if (!task.expire())
+            return;
+        _pendingTasks.decrementAndGet();
+        // Tasks are expected to run at the end of the tick of their deadline.
+        long lateness = Math.max(0, now - (task._deadline / _tickNanos + 1) * _tickNanos);
+        _lateness.record(TimeUnit.NANOSECONDS.toMillis(lateness));
+        boolean late = lateness > _tickNanos;
+        if (late)
+            _lateTasks.increment();
+        if (task._component != null)
+            task._component.onExpired(late);
+        try
+        {
+            task._task.run();
+        }
+        catch (Throwable x)
+        {
+            LOG.warn("Exception while executing task {}", task._task, x);
+        }
+    }
+
+    private void onCancelled(WheelTask task)
+    {
+        _pendingTasks.decrementAndGet();
+        if (task._component != null)
+            task._component.onCancelled();
+    }
+
+    /**
+     * <p>Returns the scheduler for the given component, creating it if necessary.</p>
+     * <p>The component scheduler schedules tasks on this wheel, so its own
+     * lifecycle is irrelevant, but tracks the statistics of its tasks only.</p>
+     *
+     * @param component the component name
+     * @return the scheduler for the given component
+     */
+    public ComponentScheduler getComponentScheduler(String component)
+    {
+        return _components.computeIfAbsent(component, ComponentScheduler::new);
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void resetStatistics()
+    {
+        _overflowTasks.reset();
+        _lateTasks.reset();
+        _lateness.reset();
+        _components.values().forEach(ComponentScheduler::resetStatistics);
+    }
+
+    @ManagedAttribute("The name of the scheduler")
+    public String getName()
+    {
+        return _name;
+    }
+
+    @ManagedAttribute("Whether the scheduler uses daemon threads")
+    public boolean isDaemon()
+    {
+        return _daemon;
+    }
+
+    @ManagedAttribute("The duration of a wheel tick in milliseconds")
+    public long getTickMillis()
+    {
+        return TimeUnit.NANOSECONDS.toMillis(_tickNanos);
+    }
+
+    @ManagedAttribute("The number of buckets of the wheel")
+    public int getWheelSize()
+    {
+        return _wheel.length;
+    }
+
+    @ManagedAttribute("The number of tasks scheduled and not yet expired or cancelled")
+    public long getPendingTasks()
+    {
+        return _pendingTasks.get();
+    }
+
+    @ManagedAttribute("The number of tasks delayed by more than a wheel revolution")
+    public long getOverflowTasks()
+    {
+        return _overflowTasks.sum();
+    }
+
+    @ManagedAttribute("The number of tasks run more than one tick late")
+    public long getLateTasks()
+    {
+        return _lateTasks.sum();
+    }
+
+    @ManagedAttribute("The maximum task lateness in milliseconds")
+    public long getMaxLateness()
+    {
+        return _lateness.getMax();
+    }
+
+    @ManagedAttribute("The mean task lateness in milliseconds")
+    public double getMeanLateness()
+    {
+        return _lateness.getMean();
+    }
+
+    @Override
+    public String dump()
+    {
+        return Dumpable.dump(this);
+    }
+
+    @Override
+    public void dump(Appendable out, String indent) throws IOException
+    {
+        Dumpable.dumpObjects(out, indent, this, new DumpableCollection("components", _components.values()));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s]{pending=%d,overflow=%d,late=%d}", getClass().getSimpleName(), hashCode(), getState(), getPendingTasks(), getOverflowTasks(), getLateTasks());
+    }
+
+    private static class WheelTask implements Task
+    {
+        private static final AtomicIntegerFieldUpdater<WheelTask> STATE = AtomicIntegerFieldUpdater.newUpdater(WheelTask.class, "_state");
+        private static final int PENDING = 0;
+        private static final int CANCELLED = 1;
+        private static final int EXPIRED = 2;
+
+        private final HashedWheelScheduler _scheduler;
+        private final ComponentScheduler _component;
+        private final Runnable _task;
+        private final long _deadline;
+        private volatile int _state;
+        // Only accessed by the wheel thread.
+        private long _rounds;
+        private WheelTask _next;
+
+        private WheelTask(HashedWheelScheduler scheduler, ComponentScheduler component, Runnable task, long deadline)
+        {
+            _scheduler = scheduler;
+            _component = component;
+            _task = task;
+            _deadline = deadline;
+        }
+
+        @Override
+        public boolean cancel()
+        {
+            if (!STATE.compareAndSet(this, PENDING, CANCELLED))
+                return false;
+            _scheduler.onCancelled(this);
+            return true;
+        }
+
+        private boolean isCancelled()
+        {
+            return _state == CANCELLED;
+        }
+
+        private boolean expire()
+        {
+            return STATE.compareAndSet(this, PENDING, EXPIRED);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s.%s@%x[%s]", HashedWheelScheduler.class.getSimpleName(), WheelTask.class.getSimpleName(), hashCode(), _task);
+        }
+    }
+
+    /**
+     * <p>A {@link Scheduler} that schedules tasks on the {@link HashedWheelScheduler}
+     * wheel and tracks timeout statistics for a single component.</p>
+     */
+    @ManagedObject
+    public class ComponentScheduler extends AbstractLifeCycle implements Scheduler
+    {
+        private final AtomicLong _pending = new AtomicLong();
+        private final LongAdder _scheduled = new LongAdder();
+        private final LongAdder _cancelled = new LongAdder();
+        private final LongAdder _expired = new LongAdder();
+        private final LongAdder _late = new LongAdder();
+        private final String _component;
+
+        private ComponentScheduler(String component)
+        {
+            _component = component;
+        }
+
+        @Override
+        public Task schedule(Runnable task, long delay, TimeUnit unit)
+        {
+            return HashedWheelScheduler.this.schedule(this, task, delay, unit);
+        }
+
+        private void onScheduled()
+        {
+            _pending.incrementAndGet();
+            _scheduled.increment();
+        }
+
+        private void onCancelled()
+        {
+            _pending.decrementAndGet();
+            _cancelled.increment();
+        }
+
+        private void onExpired(boolean late)
+        {
+            _pending.decrementAndGet();
+            _expired.increment();
+            if (late)
+                _late.increment();
+        }
+
+        private void clear()
+        {
+            _pending.set(0);
+        }
+
+        private void resetStatistics()
+        {
+            _scheduled.reset();
+            _cancelled.reset();
+            _expired.reset();
+            _late.reset();
+        }
+
+        @ManagedAttribute("The component name")
+        public String getComponent()
+        {
+            return _component;
+        }
+
+        @ManagedAttribute("The number of tasks of the component not yet expired or cancelled")
+        public long getPendingTasks()
+        {
+            return _pending.get();
+        }
+
+        @ManagedAttribute("The number of tasks scheduled by the component")
+        public long getScheduledTasks()
+        {
+            return _scheduled.sum();
+        }
+
+        @ManagedAttribute("The number of tasks of the component cancelled before their deadline")
+        public long getCancelledTasks()
+        {
+            return _cancelled.sum();
+        }
+
+        @ManagedAttribute("The number of tasks of the component run at their deadline")
+        public long getExpiredTasks()
+        {
+            return _expired.sum();
+        }
+
+        @ManagedAttribute("The number of tasks of the component run more than one tick late")
+        public long getLateTasks()
+        {
+            return _late.sum();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]{pending=%d,scheduled=%d,cancelled=%d,expired=%d,late=%d}",
+                getClass().getSimpleName(), hashCode(), _component, getPendingTasks(), getScheduledTasks(), getCancelledTasks(), getExpiredTasks(), getLateTasks());
+        }
+    }
+}
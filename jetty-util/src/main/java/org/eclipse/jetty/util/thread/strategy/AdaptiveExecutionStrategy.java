@@ -17,10 +17,14 @@ import java.io.Closeable;
 import java.time.ZonedDateTime;
 import java.time.format.DateTimeFormatter;
 import java.util.concurrent.Executor;
+import java.util.Set;
+import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.RejectedExecutionException;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.LongAdder;
 
 import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.NanoTime;
 import org.eclipse.jetty.util.VirtualThreads;
 import org.eclipse.jetty.util.annotation.ManagedAttribute;
 import org.eclipse.jetty.util.annotation.ManagedObject;
@@ -86,6 +90,16 @@ import org.slf4j.LoggerFactory;
  * This avoids starvation as the production on the second strategy can always be executed,
  * but without the risk that it may block the last available producer for the first strategy.</p>
  *
+ * <p>Tasks may declare an {@link Invocable.InvocationType} that does not reflect what they
+ * actually do, for example a {@link Invocable.InvocationType#NON_BLOCKING} task that calls a
+ * blocking API, holding the producing thread (or pinning the carrier of a virtual producing
+ * thread) and stalling production. When a {@link #setBlockingThreshold(long) blocking threshold}
+ * is configured, the tasks consumed in PC and PIC modes are timed, and the classes of the tasks
+ * that run longer than the threshold are thereafter considered {@link Invocable.InvocationType#BLOCKING},
+ * so that they are consumed in EPC or PEC mode, the latter using virtual threads if available.
+ * The detection is a timing heuristic: a task that is slow because it is CPU intensive is also
+ * considered blocking.</p>
+ *
  * <p>This strategy was previously named EatWhatYouKill (EWYK) because its preference for a
  * producer to directly consume the tasks that it produces is similar to a hunting proverb
  * that says that a hunter should eat (i.e. consume) what they kill (i.e. produced).</p>
@@ -94,6 +108,7 @@ import org.slf4j.LoggerFactory;
 public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements ExecutionStrategy, Runnable
 {
     private static final Logger LOG = LoggerFactory.getLogger(AdaptiveExecutionStrategy.class);
+    private static final long DEFAULT_BLOCKING_THRESHOLD = Long.getLong("org.eclipse.jetty.util.thread.strategy.AdaptiveExecutionStrategy.blockingThreshold", -1L);
 
     /**
      * The production state of the strategy.
@@ -133,12 +148,15 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
     private final LongAdder _picMode = new LongAdder();
     private final LongAdder _pecMode = new LongAdder();
     private final LongAdder _epcMode = new LongAdder();
+    private final LongAdder _blockingDetected = new LongAdder();
+    private final Set<Class<?>> _blockingTasks = ConcurrentHashMap.newKeySet();
     private final Producer _producer;
     private final Executor _executor;
     private final TryExecutor _tryExecutor;
     private final Executor _virtualExecutor;
     private State _state = State.IDLE;
     private boolean _pending;
+    private volatile long _blockingThreshold = DEFAULT_BLOCKING_THRESHOLD < 0 ? -1 : TimeUnit.MILLISECONDS.toNanos(DEFAULT_BLOCKING_THRESHOLD);
 
     /**
      * @param producer The producer of tasks to be consumed.
@@ -294,7 +312,7 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
      */
     private SubStrategy selectSubStrategy(Runnable task, boolean nonBlocking)
     {
-        Invocable.InvocationType taskType = Invocable.getInvocationType(task);
+        Invocable.InvocationType taskType = getInvocationType(task);
         switch (taskType)
         {
             case NON_BLOCKING:
@@ -356,6 +374,19 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
         }
     }
 
+    /**
+     * @param task The task to get the invocation type of.
+     * @return the invocation type of the task, or {@link Invocable.InvocationType#BLOCKING}
+     * if the task class has been detected as blocking.
+     */
+    private Invocable.InvocationType getInvocationType(Runnable task)
+    {
+        Invocable.InvocationType taskType = Invocable.getInvocationType(task);
+        if (taskType != Invocable.InvocationType.BLOCKING && !_blockingTasks.isEmpty() && _blockingTasks.contains(task.getClass()))
+            return Invocable.InvocationType.BLOCKING;
+        return taskType;
+    }
+
     /**
      * Consumes a task with a sub-strategy.
      *
@@ -371,14 +402,22 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
         switch (subStrategy)
         {
             case PRODUCE_CONSUME:
+            {
                 _pcMode.increment();
+                long start = _blockingThreshold < 0 ? 0 : NanoTime.now();
                 runTask(task);
+                detectBlocking(task, start);
                 return true;
+            }
 
             case PRODUCE_INVOKE_CONSUME:
+            {
                 _picMode.increment();
+                long start = _blockingThreshold < 0 ? 0 : NanoTime.now();
                 invokeAsNonBlocking(task);
+                detectBlocking(task, start);
                 return true;
+            }
 
             case PRODUCE_EXECUTE_CONSUME:
                 _pecMode.increment();
@@ -409,6 +448,27 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
         }
     }
 
+    /**
+     * Records the task class as blocking if the task, consumed by the producing thread,
+     * ran for longer than the blocking threshold.
+     *
+     * @param task The task that was consumed.
+     * @param start The nanoTime at which the task was started.
+     */
+    private void detectBlocking(Runnable task, long start)
+    {
+        long threshold = _blockingThreshold;
+        if (threshold < 0)
+            return;
+        long elapsed = NanoTime.since(start);
+        if (elapsed > threshold && _blockingTasks.add(task.getClass()))
+        {
+            _blockingDetected.increment();
+            if (LOG.isDebugEnabled())
+                LOG.debug("{} detected blocking task {} ({}ms)", this, task, TimeUnit.NANOSECONDS.toMillis(elapsed));
+        }
+    }
+
     /**
      * Runs a Runnable task, logging any thrown exception.
      *
@@ -494,6 +554,57 @@ public class AdaptiveExecutionStrategy extends ContainerLifeCycle implements Exe
         return _virtualExecutor != null;
     }
 
+    /**
+     * @return the time in milliseconds after which a task consumed by the producing
+     * thread is considered blocking, or a negative value if blocking detection is disabled
+     */
+    @ManagedAttribute("the time in ms after which a task consumed by the producing thread is considered blocking, or -1 to disable detection")
+    public long getBlockingThreshold()
+    {
+        long threshold = _blockingThreshold;
+        return threshold < 0 ? -1 : TimeUnit.NANOSECONDS.toMillis(threshold);
+    }
+
+    /**
+     * <p>Sets the time after which a task consumed by the producing thread in PC or PIC
+     * mode is considered blocking; subsequent tasks of the same class are consumed in
+     * EPC or PEC mode.</p>
+     *
+     * @param blockingThreshold the time in milliseconds, or a negative value to disable blocking detection
+     */
+    public void setBlockingThreshold(long blockingThreshold)
+    {
+        _blockingThreshold = blockingThreshold < 0 ? -1 : TimeUnit.MILLISECONDS.toNanos(blockingThreshold);
+    }
+
+    @ManagedAttribute(value = "number of task classes detected as blocking", readonly = true)
+    public long getBlockingTasksDetected()
+    {
+        return _blockingDetected.longValue();
+    }
+
+    @ManagedAttribute(value = "the task classes detected as blocking", readonly = true)
+    public String[] getBlockingTasks()
+    {
+        return _blockingTasks.stream().map(Class::getName).sorted().toArray(String[]::new);
+    }
+
+    /**
+     * @param taskClass the task class
+     * @return whether the given task class has been detected as blocking
+     */
+    public boolean isBlockingTask(Class<?> taskClass)
+    {
+        return _blockingTasks.contains(taskClass);
+    }
+
+    @ManagedOperation(value = "forgets the task classes detected as blocking", impact = "ACTION")
+    public void resetBlockingTasks()
+    {
+        _blockingTasks.clear();
+        _blockingDetected.reset();
+    }
+
     @ManagedAttribute(value = "number of tasks consumed with PC mode", readonly = true)
     public long getPCTasksConsumed()
     {
@@ -0,0 +1,153 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.List;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.empty;
+import static org.hamcrest.Matchers.is;
+
+public class BoundaryFlushingOutputStreamTest
+{
+    @Test
+    public void testJsonArrayElements() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.json(1));
+        output.setFlushBytes(1);
+        output.setFlushDelay(-1);
+
+        write(output, "[{\"a\":\"},]\",\"b\":[1,2]}");
+        write(output, ",{\"c\":3}]");
+
+        // Nested separators and separators in strings are not boundaries.
+        assertThat(recorder.flushed, contains("[{\"a\":\"},]\",\"b\":[1,2]}", ",", "{\"c\":3}", "]"));
+    }
+
+    @Test
+    public void testNewlineDelimitedJson() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.json(0));
+        output.setFlushBytes(1);
+
+        write(output, "{\"id\":1}\n{\"id\":2}\n{\"id\"");
+
+        assertThat(recorder.flushed, contains("{\"id\":1}", "\n", "{\"id\":2}", "\n"));
+        assertThat(recorder.pending(), is("{\"id\""));
+    }
+
+    @Test
+    public void testHtmlEndTags() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.html());
+        output.setFlushBytes(1);
+
+        write(output, "<!DOCTYPE html><p title=\"</b>\">one</p><!-- <x> --><br><div>two");
+
+        assertThat(recorder.flushed, contains("<!DOCTYPE html>", "<p title=\"</b>\">one</p>", "<!-- <x> -->"));
+        assertThat(recorder.pending(), is("<br><div>two"));
+    }
+
+    @Test
+    public void testFlushBytesBudget() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.lines());
+        output.setFlushBytes(8);
+        output.setFlushDelay(-1);
+
+        write(output, "ab\ncd\nef\ngh\n");
+
+        // Boundaries are only flushed once enough bytes have been written.
+        assertThat(recorder.flushed, contains("ab\ncd\nef\n"));
+        assertThat(recorder.pending(), is("gh\n"));
+        assertThat(output.getUnflushedBytes(), is(3L));
+    }
+
+    @Test
+    public void testFlushDelayBudget() throws Exception
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.lines());
+        output.setFlushBytes(1024);
+        output.setFlushDelay(0);
+
+        write(output, "ab\ncd");
+
+        assertThat(recorder.flushed, contains("ab\n"));
+    }
+
+    @Test
+    public void testMaxUnflushedBytes() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.html());
+        output.setMaxUnflushedBytes(4);
+
+        write(output, "0123456789");
+
+        assertThat(recorder.flushed, contains("0123", "4567"));
+        assertThat(recorder.pending(), is("89"));
+    }
+
+    @Test
+    public void testNoBoundaryNoFlush() throws IOException
+    {
+        FlushRecorder recorder = new FlushRecorder();
+        BoundaryFlushingOutputStream output = new BoundaryFlushingOutputStream(recorder, BoundaryFlushingOutputStream.Boundary.json(1));
+        output.setFlushBytes(1);
+
+        for (byte b : "{\"a\":\"b\"".getBytes(StandardCharsets.UTF_8))
+        {
+            output.write(b);
+        }
+        assertThat(recorder.flushed, empty());
+
+        output.flush();
+        assertThat(recorder.flushed, contains("{\"a\":\"b\""));
+        assertThat(output.getFlushes(), is(1L));
+    }
+
+    private static void write(BoundaryFlushingOutputStream output, String content) throws IOException
+    {
+        output.write(content.getBytes(StandardCharsets.UTF_8));
+    }
+
+    private static class FlushRecorder extends ByteArrayOutputStream
+    {
+        private final List<String> flushed = new ArrayList<>();
+
+        @Override
+        public void flush()
+        {
+            flushed.add(pending());
+            reset();
+        }
+
+        private String pending()
+        {
+            return toString(StandardCharsets.UTF_8);
+        }
+    }
+}
@@ -0,0 +1,194 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util;
+
+import java.io.ByteArrayInputStream;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.List;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+// @checkstyle-disable-check : AvoidEscapedUnicodeCharactersCheck
+public class ParameterParserTest
+{
+    @Test
+    public void testParseQuery()
+    {
+        MultiMap<String> map = new MultiMap<>();
+        new ParameterParser().parse("a=1&b=x+y&a=%32&c&d=e=f&&=", null, map);
+        assertThat(map.getValues("a"), contains("1", "2"));
+        assertThat(map.getValue("b"), is("x y"));
+        assertThat(map.getValue("c"), is(""));
+        assertThat(map.getValue("d"), is("e=f"));
+        assertThat(map.size(), is(4));
+    }
+
+    @Test
+    public void testParseForm() throws Exception
+    {
+        MultiMap<String> map = new MultiMap<>();
+        byte[] content = "name=Bj%C3%B6rk&city=Reykjavík".getBytes(StandardCharsets.UTF_8);
+        new ParameterParser().parse(new ByteArrayInputStream(content), StandardCharsets.UTF_8, map);
+        assertThat(map.getValue("name"), is("Björk"));
+        assertThat(map.getValue("city"), is("Reykjavík"));
+    }
+
+    @Test
+    public void testParseFormUTF16() throws Exception
+    {
+        MultiMap<String> map = new MultiMap<>();
+        byte[] content = "name=Björk".getBytes(StandardCharsets.UTF_16);
+        new ParameterParser().parse(new ByteArrayInputStream(content), StandardCharsets.UTF_16, map);
+        assertThat(map.getValue("name"), is("Björk"));
+    }
+
+    @Test
+    public void testCharsetParameter() throws Exception
+    {
+        MultiMap<String> map = new MultiMap<>();
+        byte[] content = "name=Bj%F6rk&_charset_=ISO-8859-1".getBytes(StandardCharsets.US_ASCII);
+        new ParameterParser().parse(new ByteArrayInputStream(content), null, map);
+        assertThat(map.getValue("name"), is("Björk"));
+        assertThat(map.getValue(ParameterParser.CHARSET_PARAMETER), is("ISO-8859-1"));
+
+        // An explicit charset takes precedence.
+        map.clear();
+        new ParameterParser().parse("name=Bj%C3%B6rk&_charset_=ISO-8859-1", StandardCharsets.UTF_8, map);
+        assertThat(map.getValue("name"), is("Björk"));
+
+        // The _charset_ field may be disabled.
+        map.clear();
+        ParameterParser parser = new ParameterParser();
+        parser.setCharsetParameter(false);
+        parser.parse("name=Bj%C3%B6rk&_charset_=ISO-8859-1", null, map);
+        assertThat(map.getValue("name"), is("Björk"));
+    }
+
+    @Test
+    public void testUnsupportedCharsetParameter()
+    {
+        ParameterParser parser = new ParameterParser();
+        ParameterParser.ViolationException x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse("name=value&_charset_=NO-SUCH-CHARSET", null, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.UNSUPPORTED_CHARSET));
+    }
+
+    @Test
+    public void testMaxKeys()
+    {
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxKeys(2);
+
+        // Repeated keys do not count.
+        MultiMap<String> map = new MultiMap<>();
+        parser.parse("a=1&b=2&a=3", null, map);
+        assertThat(map.size(), is(2));
+
+        ParameterParser.ViolationException x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse("a=1&b=2&c=3", null, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.TOO_MANY_KEYS));
+    }
+
+    @Test
+    public void testMaxValueLength()
+    {
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxValueLength(3);
+
+        MultiMap<String> map = new MultiMap<>();
+        // The decoded length is checked.
+        parser.parse("a=%41%42%43", null, map);
+        assertThat(map.getValue("a"), is("ABC"));
+
+        ParameterParser.ViolationException x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse("a=1234", null, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.VALUE_TOO_LONG));
+    }
+
+    @Test
+    public void testMaxLength()
+    {
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxLength(8);
+
+        byte[] content = "a=1&b=2&c=3".getBytes(StandardCharsets.US_ASCII);
+        ParameterParser.ViolationException x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse(new ByteArrayInputStream(content), null, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.CONTENT_TOO_LARGE));
+    }
+
+    @Test
+    public void testToleratedViolations() throws Exception
+    {
+        List<ParameterParser.Violation> violations = new ArrayList<>();
+        ParameterParser parser = new ParameterParser();
+        parser.setMaxLength(12);
+        parser.setMaxKeys(2);
+        parser.setMaxValueLength(2);
+        parser.setViolationListener((violation, message) -> violations.add(violation));
+
+        MultiMap<String> map = new MultiMap<>();
+        byte[] content = "a=1&b=long&c=3&d=4&e=5".getBytes(StandardCharsets.US_ASCII);
+        parser.parse(new ByteArrayInputStream(content), null, map);
+
+        // The content is truncated to "a=1&b=long&c", and the incomplete "c" is ignored.
+        // The value of "b" is too long, so it is ignored.
+        assertThat(violations, contains(ParameterParser.Violation.CONTENT_TOO_LARGE, ParameterParser.Violation.VALUE_TOO_LONG));
+        assertThat(map.getValue("a"), is("1"));
+        assertThat(map.getValue("b"), nullValue());
+        assertThat(map.getValue("c"), nullValue());
+
+        violations.clear();
+        map.clear();
+        parser.parse("a=1&b=2&c=3&d=4", null, map);
+        assertThat(violations, contains(ParameterParser.Violation.TOO_MANY_KEYS));
+        assertThat(map.size(), is(2));
+    }
+
+    @Test
+    public void testDecodingModes()
+    {
+        ParameterParser parser = new ParameterParser();
+
+        // Invalid percent-encoding.
+        parser.setDecoding(ParameterParser.Decoding.REPLACE);
+        ParameterParser.ViolationException x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse("a=100%&b=%zz", null, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.BAD_ENCODING));
+
+        parser.setDecoding(ParameterParser.Decoding.LENIENT);
+        MultiMap<String> map = new MultiMap<>();
+        parser.parse("a=100%&b=%zz", null, map);
+        assertThat(map.getValue("a"), is("100%"));
+        assertThat(map.getValue("b"), is("%zz"));
+
+        // Invalid UTF-8 sequence.
+        parser.setDecoding(ParameterParser.Decoding.REPLACE);
+        map.clear();
+        parser.parse("a=%E9", StandardCharsets.UTF_8, map);
+        assertThat(map.getValue("a"), is("\uFFFD"));
+
+        parser.setDecoding(ParameterParser.Decoding.STRICT);
+        x = assertThrows(ParameterParser.ViolationException.class,
+            () -> parser.parse("a=%E9", StandardCharsets.UTF_8, new MultiMap<>()));
+        assertThat(x.getViolation(), is(ParameterParser.Violation.BAD_ENCODING));
+    }
+}
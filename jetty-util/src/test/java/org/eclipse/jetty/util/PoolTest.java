@@ -17,8 +17,12 @@ import java.io.Closeable;
 import java.util.ArrayList;
 import java.util.Arrays;
 import java.util.HashMap;
+import java.util.HashSet;
 import java.util.List;
 import java.util.Map;
+import java.util.Set;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicInteger;
 import java.util.stream.Stream;
 
@@ -30,6 +34,7 @@ import static java.util.stream.Collectors.toList;
 import static org.eclipse.jetty.util.Pool.StrategyType.FIRST;
 import static org.eclipse.jetty.util.Pool.StrategyType.RANDOM;
 import static org.eclipse.jetty.util.Pool.StrategyType.ROUND_ROBIN;
+import static org.eclipse.jetty.util.Pool.StrategyType.STRIPED;
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.equalTo;
 import static org.hamcrest.Matchers.greaterThan;
@@ -73,6 +78,7 @@ public class PoolTest
         data.add(new Object[]{(Factory)s -> new Pool<>(RANDOM, s)});
         data.add(new Object[]{(Factory)s -> new Pool<>(FIRST, s, true)});
         data.add(new Object[]{(Factory)s -> new Pool<>(ROUND_ROBIN, s)});
+        data.add(new Object[]{(Factory)s -> new Pool<>(STRIPED, s)});
         return data.stream();
     }
 
@@ -120,6 +126,32 @@ public class PoolTest
         assertThrows(IllegalStateException.class, () -> pool.release(e2));
     }
 
+    @ParameterizedTest
+    @MethodSource(value = "strategy")
+    public void testAcquireEntry(Factory factory)
+    {
+        Pool<CloseableHolder> pool = factory.getPool(2);
+        pool.setMaxMultiplex(2);
+        Pool<CloseableHolder>.Entry e1 = pool.reserve();
+        // Reserved entries cannot be acquired.
+        assertThat(pool.acquire(e1), is(false));
+        e1.enable(new CloseableHolder("aaa"), false);
+        assertThat(e1.getMultiplexCount(), is(0));
+
+        assertThat(pool.acquire(e1), is(true));
+        assertThat(e1.getMultiplexCount(), is(1));
+        assertThat(pool.acquire(e1), is(true));
+        assertThat(e1.getMultiplexCount(), is(2));
+        // Max multiplex reached.
+        assertThat(pool.acquire(e1), is(false));
+
+        assertThat(pool.release(e1), is(true));
+        assertThat(e1.getMultiplexCount(), is(1));
+
+        pool.close();
+        assertThat(pool.acquire(e1), is(false));
+    }
+
     @ParameterizedTest
     @MethodSource(value = "strategy")
     public void testRemoveBeforeRelease(Factory factory)
@@ -690,4 +722,72 @@ public class PoolTest
         assertThat(e3.getPooled().get(), greaterThan(10));
         assertThat(e4.getPooled().get(), greaterThan(10));
     }
+
+    @Test
+    public void testStripedStrategyStealsFromOtherStripes()
+    {
+        int size = 4 * ProcessorUtils.availableProcessors() + 3;
+        Pool<AtomicInteger> pool = new Pool<>(STRIPED, size);
+        for (int i = 0; i < size; i++)
+        {
+            pool.reserve().enable(new AtomicInteger(), false);
+        }
+
+        // A single thread must be able to acquire every entry, not only those of its home stripe.
+        Set<Pool<AtomicInteger>.Entry> acquired = new HashSet<>();
+        for (int i = 0; i < size; i++)
+        {
+            Pool<AtomicInteger>.Entry entry = pool.acquire();
+            assertThat(entry, notNullValue());
+            assertThat(acquired.add(entry), is(true));
+        }
+        assertNull(pool.acquire());
+
+        acquired.forEach(pool::release);
+        assertThat(pool.getIdleCount(), is(size));
+    }
+
+    @Test
+    public void testStripedStrategyConcurrentAcquireRelease() throws Exception
+    {
+        int size = 16;
+        Pool<AtomicInteger> pool = new Pool<>(STRIPED, size);
+        for (int i = 0; i < size; i++)
+        {
+            pool.reserve().enable(new AtomicInteger(), false);
+        }
+
+        int threads = 8;
+        int iterations = 10_000;
+        AtomicInteger failures = new AtomicInteger();
+        CountDownLatch latch = new CountDownLatch(threads);
+        for (int t = 0; t < threads; t++)
+        {
+            new Thread(() ->
+            {
+                try
+                {
+                    for (int i = 0; i < iterations; i++)
+                    {
+                        Pool<AtomicInteger>.Entry entry = pool.acquire();
+                        if (entry == null)
+                            continue;
+                        // Only one thread at a time may own an entry.
+                        if (entry.getPooled().incrementAndGet() != 1)
+                            failures.incrementAndGet();
+                        entry.getPooled().decrementAndGet();
+                        pool.release(entry);
+                    }
+                }
+                finally
+                {
+                    latch.countDown();
+                }
+            }).start();
+        }
+
+        assertThat(latch.await(30, TimeUnit.SECONDS), is(true));
+        assertThat(failures.get(), is(0));
+        assertThat(pool.getIdleCount(), is(size));
+    }
 }
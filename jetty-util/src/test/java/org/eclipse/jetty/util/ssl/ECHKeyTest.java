@@ -0,0 +1,120 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.security.GeneralSecurityException;
+import java.util.List;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.resource.Resource;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+import org.junit.jupiter.params.ParameterizedTest;
+import org.junit.jupiter.params.provider.ValueSource;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class ECHKeyTest
+{
+    public WorkDir workDir;
+
+    @ParameterizedTest
+    @ValueSource(ints = {HPKE.AEAD_AES_128_GCM, HPKE.AEAD_AES_256_GCM, HPKE.AEAD_CHACHA20_POLY1305})
+    public void testHPKESealOpen(int aeadId) throws Exception
+    {
+        byte[] publicKey = HPKE.encodePublicKey(HPKE.generateKeyPair().getPublic());
+        assertThat(publicKey.length, is(32));
+
+        ECHKey key = ECHKey.generate(1, "public.example.com");
+        byte[] info = "info".getBytes(StandardCharsets.US_ASCII);
+        HPKE.Sender sender = HPKE.setupBaseS(HPKE.KDF_HKDF_SHA256, aeadId, key.getConfig().getPublicKey(), info);
+        HPKE.Context recipient = HPKE.setupBaseR(HPKE.KDF_HKDF_SHA256, aeadId, key.getPrivateKey(), sender.getEncapsulatedKey(), info);
+
+        byte[] aad = {1, 2, 3};
+        for (int i = 0; i < 3; ++i)
+        {
+            byte[] message = ("message" + i).getBytes(StandardCharsets.US_ASCII);
+            byte[] cipherText = sender.seal(aad, message);
+            assertThat(recipient.open(aad, cipherText), is(message));
+        }
+        assertThat(recipient.export(info, 16), is(sender.export(info, 16)));
+
+        // A different AAD fails the authentication.
+        byte[] cipherText = sender.seal(aad, new byte[8]);
+        assertThrows(GeneralSecurityException.class, () -> recipient.open(new byte[]{4}, cipherText));
+    }
+
+    @Test
+    public void testECHConfigList() throws Exception
+    {
+        ECHKey key1 = ECHKey.generate(1, "public.example.com");
+        ECHKey key2 = ECHKey.generate(2, "other.example.com");
+        byte[] list = ECHConfig.encodeList(List.of(key1.getConfig(), key2.getConfig()));
+
+        List<ECHConfig> configs = ECHConfig.parseList(list);
+        assertThat(configs, hasSize(2));
+        assertThat(configs.get(0), is(key1.getConfig()));
+        assertThat(configs.get(1).getConfigId(), is(2));
+        assertThat(configs.get(1).getPublicName(), is("other.example.com"));
+        assertThat(configs.get(1).isSupported(), is(true));
+
+        // Configurations with unknown versions are skipped.
+        byte[] unknown = {0, 6, 0x12, 0x34, 0, 2, 0, 0};
+        byte[] mixed = new byte[unknown.length - 2 + list.length - 2 + 2];
+        int length = mixed.length - 2;
+        mixed[0] = (byte)(length >>> 8);
+        mixed[1] = (byte)length;
+        System.arraycopy(unknown, 2, mixed, 2, unknown.length - 2);
+        System.arraycopy(list, 2, mixed, unknown.length, list.length - 2);
+        assertThat(ECHConfig.parseList(mixed), is(configs));
+
+        assertThrows(IllegalArgumentException.class, () -> ECHConfig.parseList(new byte[]{0, 10, 1}));
+    }
+
+    @Test
+    public void testLoadPEM() throws Exception
+    {
+        ECHKey key = ECHKey.generate(7, "public.example.com");
+        Path pem = workDir.getEmptyPathDir().resolve("ech.pem");
+        Files.writeString(pem, key.toPEM(), StandardCharsets.US_ASCII);
+
+        List<ECHKey> keys = ECHKey.load(pem);
+        assertThat(keys, hasSize(1));
+        assertThat(keys.get(0).getConfig(), is(key.getConfig()));
+        assertThat(HPKE.encodePublicKey(keys.get(0).getPrivateKey()), is(key.getConfig().getPublicKey()));
+
+        SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStoreResource(Resource.newSystemResource("keystore.p12"));
+        sslContextFactory.setKeyStorePassword("storepwd");
+        sslContextFactory.setECHKeyPath(pem.toString());
+        sslContextFactory.start();
+        try
+        {
+            assertThat(sslContextFactory.getECHKeys(), hasSize(1));
+            assertThat(sslContextFactory.getECHKeys().get(0).getConfig().getConfigId(), is(7));
+        }
+        finally
+        {
+            sslContextFactory.stop();
+        }
+    }
+}
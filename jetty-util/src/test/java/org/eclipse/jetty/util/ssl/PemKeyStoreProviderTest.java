@@ -0,0 +1,163 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.InputStream;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.security.Key;
+import java.security.KeyPairGenerator;
+import java.security.KeyStore;
+import java.security.cert.Certificate;
+import java.util.Base64;
+import java.util.Collections;
+import java.util.List;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.resource.Resource;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class PemKeyStoreProviderTest
+{
+    public WorkDir workDir;
+
+    private Path dir;
+    private Key key;
+    private Certificate[] chain;
+    private SslContextFactory.Server sslContextFactory;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        try (InputStream keystoreInputStream = Resource.newSystemResource("keystore.p12").getInputStream())
+        {
+            keyStore.load(keystoreInputStream, "storepwd".toCharArray());
+        }
+        String alias = null;
+        for (String a : Collections.list(keyStore.aliases()))
+        {
+            if (keyStore.isKeyEntry(a))
+                alias = a;
+        }
+        assertNotNull(alias);
+        key = keyStore.getKey(alias, "storepwd".toCharArray());
+        chain = keyStore.getCertificateChain(alias);
+
+        dir = workDir.getEmptyPathDir();
+        sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStorePassword("secret");
+    }
+
+    private String chainPem() throws Exception
+    {
+        StringBuilder builder = new StringBuilder();
+        for (Certificate certificate : chain)
+        {
+            builder.append(toPem("CERTIFICATE", certificate.getEncoded()));
+        }
+        return builder.toString();
+    }
+
+    private static String toPem(String type, byte[] bytes)
+    {
+        Base64.Encoder encoder = Base64.getMimeEncoder(64, "\n".getBytes(StandardCharsets.US_ASCII));
+        return "-----BEGIN " + type + "-----\n" + encoder.encodeToString(bytes) + "\n-----END " + type + "-----\n";
+    }
+
+    @Test
+    public void testSeparateFiles() throws Exception
+    {
+        Path certificatePath = Files.writeString(dir.resolve("certificate.pem"), chainPem());
+        Path keyPath = Files.writeString(dir.resolve("key.pem"), toPem("PRIVATE KEY", key.getEncoded()));
+        PemKeyStoreProvider provider = new PemKeyStoreProvider(certificatePath, keyPath);
+        provider.setAlias("server");
+
+        KeyStore keyStore = provider.getKeyStore(sslContextFactory);
+
+        assertThat(Collections.list(keyStore.aliases()), is(List.of("server")));
+        assertArrayEquals(chain, keyStore.getCertificateChain("server"));
+        assertArrayEquals(key.getEncoded(), keyStore.getKey("server", "secret".toCharArray()).getEncoded());
+        assertThat(provider.getPaths(), is(List.of(certificatePath, keyPath)));
+    }
+
+    @Test
+    public void testSingleFile() throws Exception
+    {
+        Path path = Files.writeString(dir.resolve("server.pem"), toPem("PRIVATE KEY", key.getEncoded()) + chainPem());
+        PemKeyStoreProvider provider = new PemKeyStoreProvider(path, path);
+
+        KeyStore keyStore = provider.getKeyStore(sslContextFactory);
+
+        assertArrayEquals(chain, keyStore.getCertificateChain("pem"));
+        assertThat(provider.getPaths(), is(List.of(path)));
+    }
+
+    @Test
+    public void testKeyManagerPasswordProtectsKey() throws Exception
+    {
+        Path path = Files.writeString(dir.resolve("server.pem"), chainPem() + toPem("PRIVATE KEY", key.getEncoded()));
+        sslContextFactory.setKeyManagerPassword("keypwd");
+
+        KeyStore keyStore = new PemKeyStoreProvider(path, path).getKeyStore(sslContextFactory);
+
+        assertNotNull(keyStore.getKey("pem", "keypwd".toCharArray()));
+    }
+
+    @Test
+    public void testMismatchedPrivateKey() throws Exception
+    {
+        KeyPairGenerator generator = KeyPairGenerator.getInstance(key.getAlgorithm());
+        Key otherKey = generator.generateKeyPair().getPrivate();
+        Path certificatePath = Files.writeString(dir.resolve("certificate.pem"), chainPem());
+        Path keyPath = Files.writeString(dir.resolve("key.pem"), toPem("PRIVATE KEY", otherKey.getEncoded()));
+
+        IllegalArgumentException failure = assertThrows(IllegalArgumentException.class, () -> new PemKeyStoreProvider(certificatePath, keyPath).getKeyStore(sslContextFactory));
+        assertThat(failure.getMessage(), containsString("does not match"));
+    }
+
+    @Test
+    public void testMissingBlocks() throws Exception
+    {
+        Path certificatePath = Files.writeString(dir.resolve("certificate.pem"), chainPem());
+        Path keyPath = Files.writeString(dir.resolve("key.pem"), toPem("PRIVATE KEY", key.getEncoded()));
+        Path emptyPath = Files.writeString(dir.resolve("empty.pem"), "");
+
+        assertThrows(IllegalArgumentException.class, () -> new PemKeyStoreProvider(emptyPath, keyPath).getKeyStore(sslContextFactory));
+        assertThrows(IllegalArgumentException.class, () -> new PemKeyStoreProvider(certificatePath, emptyPath).getKeyStore(sslContextFactory));
+        // Encrypted private keys are not supported.
+        Path encryptedPath = Files.writeString(dir.resolve("encrypted.pem"), toPem("ENCRYPTED PRIVATE KEY", key.getEncoded()));
+        assertThrows(IllegalArgumentException.class, () -> new PemKeyStoreProvider(certificatePath, encryptedPath).getKeyStore(sslContextFactory));
+    }
+
+    @Test
+    public void testMissingPassword() throws Exception
+    {
+        Path path = Files.writeString(dir.resolve("server.pem"), chainPem() + toPem("PRIVATE KEY", key.getEncoded()));
+
+        assertThrows(IllegalStateException.class, () -> new PemKeyStoreProvider(path, path).getKeyStore(new SslContextFactory.Server()));
+    }
+}
@@ -0,0 +1,209 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.ssl;
+
+import java.io.InputStream;
+import java.net.URI;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardCopyOption;
+import java.security.KeyStore;
+import java.security.cert.CertificateException;
+import java.security.cert.CertificateFactory;
+import java.security.cert.X509Certificate;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.net.ssl.X509ExtendedTrustManager;
+
+import org.eclipse.jetty.toolchain.test.MavenTestingUtils;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class RevocationCheckerTest
+{
+    public WorkDir workDir;
+    private X509Certificate ca;
+    private KeyStore trustStore;
+    private RevocationChecker checker;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        ca = certificate("ca.pem");
+        trustStore = KeyStore.getInstance("PKCS12");
+        trustStore.load(null, null);
+        trustStore.setCertificateEntry("ca", ca);
+        checker = new RevocationChecker();
+    }
+
+    @AfterEach
+    public void dispose()
+    {
+        LifeCycle.stop(checker);
+    }
+
+    private static X509Certificate certificate(String name) throws Exception
+    {
+        try (InputStream input = Files.newInputStream(MavenTestingUtils.getTestResourcePathFile("revocation/" + name)))
+        {
+            return (X509Certificate)CertificateFactory.getInstance("X.509").generateCertificate(input);
+        }
+    }
+
+    private static String crlPath(String name)
+    {
+        return MavenTestingUtils.getTestResourcePathFile("revocation/" + name).toString();
+    }
+
+    private X509ExtendedTrustManager newTrustManager() throws Exception
+    {
+        checker.start();
+        return checker.newTrustManager(new SslContextFactory.X509ExtendedTrustManagerWrapper(null), trustStore);
+    }
+
+    @Test
+    public void testRevokedByCrl() throws Exception
+    {
+        checker.setEnableOCSP(false);
+        checker.setCrlPath(crlPath("crl.pem"));
+        X509ExtendedTrustManager trustManager = newTrustManager();
+
+        assertThat(checker.getCrlCount(), is(1));
+        trustManager.checkClientTrusted(new X509Certificate[]{certificate("good.pem"), ca}, "RSA");
+        CertificateException failure = assertThrows(CertificateException.class,
+            () -> trustManager.checkClientTrusted(new X509Certificate[]{certificate("revoked.pem"), ca}, "RSA"));
+        assertThat(failure.getMessage(), containsString("Revoked"));
+        assertThat(checker.getChecks(), is(2L));
+        assertThat(checker.getRejections(), is(1L));
+
+        // The trust anchor itself is not checked.
+        trustManager.checkClientTrusted(new X509Certificate[]{ca}, "RSA");
+    }
+
+    @Test
+    public void testCrlRefresh() throws Exception
+    {
+        Path crl = workDir.getEmptyPathDir().resolve("crl.pem");
+        Files.copy(Path.of(crlPath("crl-empty.pem")), crl);
+        checker.setEnableOCSP(false);
+        checker.setCrlPath(crl.toString());
+        X509ExtendedTrustManager trustManager = newTrustManager();
+        X509Certificate[] chain = {certificate("revoked.pem"), ca};
+
+        trustManager.checkClientTrusted(chain, "RSA");
+
+        Files.copy(Path.of(crlPath("crl.pem")), crl, StandardCopyOption.REPLACE_EXISTING);
+        checker.refreshCrls();
+
+        assertThrows(CertificateException.class, () -> trustManager.checkClientTrusted(chain, "RSA"));
+    }
+
+    @Test
+    public void testUnreachableOcspResponderHardFail() throws Exception
+    {
+        X509ExtendedTrustManager trustManager = newTrustManager();
+
+        CertificateException failure = assertThrows(CertificateException.class,
+            () -> trustManager.checkClientTrusted(new X509Certificate[]{certificate("ocsp.pem"), ca}, "RSA"));
+        assertThat(failure.getMessage(), containsString("Unable to determine revocation status"));
+        assertThat(checker.getOcspRequests(), is(1L));
+        assertThat(checker.getOcspCacheEntries(), is(0));
+    }
+
+    @Test
+    public void testUnreachableOcspResponderSoftFail() throws Exception
+    {
+        checker.setFailurePolicy(RevocationChecker.FailurePolicy.SOFT_FAIL);
+        X509ExtendedTrustManager trustManager = newTrustManager();
+
+        trustManager.checkClientTrusted(new X509Certificate[]{certificate("ocsp.pem"), ca}, "RSA");
+        assertThat(checker.getSoftFailures(), is(1L));
+        assertThat(checker.getRejections(), is(0L));
+    }
+
+    @Test
+    public void testOcspResponseCache() throws Exception
+    {
+        AtomicInteger fetches = new AtomicInteger();
+        checker = new RevocationChecker()
+        {
+            @Override
+            protected byte[] fetchOcspResponse(URI responder, byte[] request)
+            {
+                fetches.incrementAndGet();
+                // Not a valid OCSP response, the check fails.
+                return new byte[]{0x30, 0x03, 0x0A, 0x01, 0x00};
+            }
+        };
+        X509ExtendedTrustManager trustManager = newTrustManager();
+        X509Certificate[] chain = {certificate("ocsp.pem"), ca};
+
+        assertThrows(CertificateException.class, () -> trustManager.checkClientTrusted(chain, "RSA"));
+        assertThat(fetches.get(), is(1));
+        assertThat(checker.getOcspCacheEntries(), is(1));
+
+        // The cached response is used, then discarded because it is not valid.
+        assertThrows(CertificateException.class, () -> trustManager.checkClientTrusted(chain, "RSA"));
+        assertThat(checker.getOcspCacheHits(), is(1L));
+        assertThat(fetches.get(), is(2));
+
+        checker.clearOcspCache();
+        assertThat(checker.getOcspCacheEntries(), is(0));
+    }
+
+    @Test
+    public void testMustStaple() throws Exception
+    {
+        X509Certificate staple = certificate("staple.pem");
+        assertThat(RevocationChecker.isMustStaple(staple), is(true));
+        assertThat(RevocationChecker.isMustStaple(certificate("good.pem")), is(false));
+
+        checker.setEnableOCSP(false);
+        checker.setCrlPath(crlPath("crl-empty.pem"));
+        X509ExtendedTrustManager trustManager = newTrustManager();
+        X509Certificate[] chain = {staple, ca};
+
+        CertificateException failure = assertThrows(CertificateException.class, () -> trustManager.checkServerTrusted(chain, "RSA"));
+        assertThat(failure.getMessage(), containsString("must-staple"));
+        // Must-staple only applies to server certificates.
+        trustManager.checkClientTrusted(chain, "RSA");
+
+        checker.stop();
+        checker.setEnforceMustStaple(false);
+        newTrustManager().checkServerTrusted(chain, "RSA");
+    }
+
+    @Test
+    public void testOcspRequest() throws Exception
+    {
+        assertThat(RevocationChecker.getOcspResponder(certificate("ocsp.pem")), is(URI.create("http://127.0.0.1:9/ocsp")));
+        assertThat(RevocationChecker.getOcspResponder(certificate("good.pem")), nullValue());
+
+        // Generated with: openssl ocsp -issuer ca.pem -cert good.pem -no_nonce -reqout good-request.der
+        byte[] expected = Files.readAllBytes(MavenTestingUtils.getTestResourcePathFile("revocation/good-request.der"));
+        assertArrayEquals(expected, RevocationChecker.newOcspRequest(certificate("good.pem"), ca));
+    }
+}
@@ -19,10 +19,16 @@ import java.io.InputStream;
 import java.io.OutputStream;
 import java.net.InetSocketAddress;
 import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.attribute.PosixFilePermissions;
+import java.security.Key;
 import java.security.KeyStore;
 import java.security.cert.Certificate;
 import java.security.cert.X509Certificate;
+import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.Base64;
 import java.util.Collections;
 import java.util.List;
 import java.util.Optional;
@@ -39,10 +45,15 @@ import javax.net.ssl.SSLSocket;
 import javax.net.ssl.X509ExtendedKeyManager;
 
 import org.eclipse.jetty.logging.StacklessLogging;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
 import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.component.AbstractLifeCycle;
 import org.eclipse.jetty.util.resource.Resource;
 import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.condition.DisabledOnOs;
+import org.junit.jupiter.api.condition.OS;
+import org.junit.jupiter.api.extension.ExtendWith;
 
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.containsInAnyOrder;
@@ -63,8 +74,11 @@ import static org.junit.jupiter.api.Assertions.assertNull;
 import static org.junit.jupiter.api.Assertions.assertThrows;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
+@ExtendWith(WorkDirExtension.class)
 public class SslContextFactoryTest
 {
+    public WorkDir workDir;
+
     @Test
     public void testSLOTH() throws Exception
     {
@@ -455,4 +469,144 @@ public class SslContextFactoryTest
         clientTLS.stop();
         serverTLS.stop();
     }
+
+    @Test
+    public void testPemKeyStoreSourceReload() throws Exception
+    {
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        try (InputStream keystoreInputStream = Resource.newSystemResource("keystore.p12").getInputStream())
+        {
+            keyStore.load(keystoreInputStream, "storepwd".toCharArray());
+        }
+        String alias = null;
+        for (String a : Collections.list(keyStore.aliases()))
+        {
+            if (keyStore.isKeyEntry(a))
+                alias = a;
+        }
+        assertNotNull(alias);
+        Key key = keyStore.getKey(alias, "storepwd".toCharArray());
+        X509Certificate certificate = (X509Certificate)keyStore.getCertificate(alias);
+
+        Path dir = workDir.getEmptyPathDir();
+        Path certificatePath = dir.resolve("certificate.pem");
+        Path keyPath = dir.resolve("key.pem");
+        List<String> pems = new ArrayList<>();
+        for (Certificate c : keyStore.getCertificateChain(alias))
+        {
+            pems.add(toPem("CERTIFICATE", c.getEncoded()));
+        }
+        Files.writeString(certificatePath, String.join("", pems));
+        Files.writeString(keyPath, toPem("PRIVATE KEY", key.getEncoded()));
+
+        SslContextFactory.Server cf = new SslContextFactory.Server();
+        cf.setKeyStorePassword("secret");
+        cf.setKeyStoreSource(new PemKeyStoreProvider(certificatePath, keyPath));
+        List<String> events = new ArrayList<>();
+        cf.addEventListener(new SslContextFactory.Listener()
+        {
+            @Override
+            public void onReloaded(SslContextFactory sslContextFactory)
+            {
+                events.add("reloaded");
+            }
+
+            @Override
+            public void onReloadFailure(SslContextFactory sslContextFactory, Throwable failure)
+            {
+                events.add("failure");
+            }
+        });
+        cf.start();
+
+        String[] activeCertificates = cf.getActiveCertificates();
+        assertThat(activeCertificates.length, is(1));
+        assertThat(activeCertificates[0], containsString("serial=" + certificate.getSerialNumber().toString(16)));
+        assertEquals(certificate.getNotAfter().getTime(), cf.getActiveCertificatesNotAfter());
+        assertNotNull(cf.newSSLEngine());
+
+        cf.reload(scf ->
+        {
+        });
+        assertEquals(1, cf.getReloadCount());
+        assertThat(events, is(List.of("reloaded")));
+
+        Files.writeString(keyPath, "not a key");
+        assertThrows(IllegalArgumentException.class, () -> cf.reload(scf ->
+        {
+        }));
+        assertEquals(1, cf.getReloadCount());
+        assertThat(events, is(List.of("reloaded", "failure")));
+
+        // The failed reload keeps the previous configuration.
+        assertThat(cf.getActiveCertificates().length, is(1));
+        assertNotNull(cf.getKeyStore());
+        assertNotNull(cf.newSSLEngine());
+
+        cf.stop();
+    }
+
+    @Test
+    public void testKeyLogRequiresSystemProperty() throws Exception
+    {
+        Path keyLogFile = workDir.getEmptyPathDir().resolve("keys.log");
+        SslContextFactory.Server cf = new SslContextFactory.Server();
+        cf.setKeyStorePassword("storepwd");
+        cf.setKeyLogFile(keyLogFile);
+
+        System.clearProperty(SslContextFactory.KEY_LOG_PROPERTY);
+        try (StacklessLogging ignore = new StacklessLogging(SslContextFactory.class))
+        {
+            cf.start();
+        }
+        assertFalse(cf.isKeyLogEnabled());
+        assertNull(cf.getEnabledKeyLogFile());
+        cf.stop();
+
+        System.setProperty(SslContextFactory.KEY_LOG_PROPERTY, "true");
+        try (StacklessLogging ignore = new StacklessLogging(SslContextFactory.class))
+        {
+            cf.start();
+        }
+        finally
+        {
+            System.clearProperty(SslContextFactory.KEY_LOG_PROPERTY);
+        }
+        assertTrue(cf.isKeyLogEnabled());
+        assertEquals(keyLogFile, cf.getEnabledKeyLogFile());
+        cf.stop();
+        assertFalse(cf.isKeyLogEnabled());
+    }
+
+    @Test
+    public void testKeyLogFile() throws Exception
+    {
+        Path keyLogFile = workDir.getEmptyPathDir().resolve("keys.log");
+        try (SslKeyLogger.File keyLogger = new SslKeyLogger.File(keyLogFile))
+        {
+            keyLogger.log("RSA Session-ID:01 Master-Key:02");
+            // Lines are flushed immediately.
+            assertThat(Files.readAllLines(keyLogFile), is(List.of("RSA Session-ID:01 Master-Key:02")));
+            keyLogger.log("RSA Session-ID:03 Master-Key:04");
+        }
+        assertThat(Files.readAllLines(keyLogFile), is(List.of("RSA Session-ID:01 Master-Key:02", "RSA Session-ID:03 Master-Key:04")));
+    }
+
+    @Test
+    @DisabledOnOs(OS.WINDOWS)
+    public void testKeyLogFileIsOwnerOnly() throws Exception
+    {
+        Path keyLogFile = workDir.getEmptyPathDir().resolve("keys.log");
+        try (SslKeyLogger.File keyLogger = new SslKeyLogger.File(keyLogFile))
+        {
+            keyLogger.log("RSA Session-ID:01 Master-Key:02");
+        }
+        assertThat(PosixFilePermissions.toString(Files.getPosixFilePermissions(keyLogFile)), is("rw-------"));
+    }
+
+    private static String toPem(String type, byte[] bytes)
+    {
+        Base64.Encoder encoder = Base64.getMimeEncoder(64, "\n".getBytes(StandardCharsets.US_ASCII));
+        return "-----BEGIN " + type + "-----\n" + encoder.encodeToString(bytes) + "\n-----END " + type + "-----\n";
+    }
 }
@@ -0,0 +1,139 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.statistic;
+
+import java.util.concurrent.TimeUnit;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.allOf;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class HistogramStatisticTest
+{
+    @Test
+    public void testEmpty()
+    {
+        HistogramStatistic histogram = new HistogramStatistic(1, TimeUnit.MINUTES);
+        assertEquals(0, histogram.getCount());
+        assertEquals(0, histogram.getMax());
+        assertEquals(0, histogram.getPercentile(99));
+        assertEquals(1, histogram.getWindow());
+        assertEquals(TimeUnit.MINUTES, histogram.getUnits());
+    }
+
+    @Test
+    public void testSmallValuesAreExact()
+    {
+        HistogramStatistic histogram = new HistogramStatistic(1, TimeUnit.MINUTES);
+        for (int i = 1; i <= 20; i++)
+        {
+            histogram.record(i);
+        }
+        assertEquals(20, histogram.getCount());
+        assertEquals(20, histogram.getMax());
+        assertEquals(10, histogram.getPercentile(50));
+        assertEquals(19, histogram.getPercentile(95));
+        assertEquals(20, histogram.getPercentile(100));
+        assertEquals(1, histogram.getPercentile(0));
+    }
+
+    @Test
+    public void testPercentilesRelativeError()
+    {
+        HistogramStatistic histogram = new HistogramStatistic(1, TimeUnit.MINUTES);
+        for (int i = 1; i <= 100_000; i++)
+        {
+            histogram.record(i);
+        }
+        HistogramStatistic.Snapshot snapshot = histogram.snapshot();
+        assertEquals(100_000, snapshot.getCount());
+        assertEquals(100_000, snapshot.getMax());
+        assertNear(50_000, snapshot.getPercentile(50));
+        assertNear(95_000, snapshot.getPercentile(95));
+        assertNear(99_000, snapshot.getPercentile(99));
+        assertNear(99_900, snapshot.getPercentile(99.9));
+    }
+
+    @Test
+    public void testOutOfRangeValues()
+    {
+        HistogramStatistic histogram = new HistogramStatistic(1, TimeUnit.MINUTES);
+        histogram.record(-1);
+        histogram.record(Long.MAX_VALUE);
+        assertEquals(2, histogram.getCount());
+        assertEquals(0, histogram.getPercentile(50));
+        assertEquals((1L << 40) - 1, histogram.getMax());
+        assertEquals((1L << 40) - 1, histogram.getPercentile(100));
+        assertThrows(IllegalArgumentException.class, () -> histogram.getPercentile(101));
+    }
+
+    @Test
+    public void testSlidingWindow()
+    {
+        ManualHistogramStatistic histogram = new ManualHistogramStatistic(60, TimeUnit.SECONDS, 6);
+        histogram.record(1000);
+        assertEquals(1, histogram.getCount());
+
+        // Still within the window.
+        histogram.advance(50, TimeUnit.SECONDS);
+        histogram.record(10);
+        assertEquals(2, histogram.getCount());
+        assertEquals(1000, histogram.getMax());
+
+        // The slot of the first sample has left the window.
+        histogram.advance(15, TimeUnit.SECONDS);
+        assertEquals(1, histogram.getCount());
+        assertEquals(10, histogram.getMax());
+
+        // Reusing a slot clears its old samples.
+        histogram.advance(60, TimeUnit.SECONDS);
+        histogram.record(20);
+        assertEquals(1, histogram.getCount());
+        assertEquals(20, histogram.getPercentile(50));
+
+        histogram.reset();
+        assertEquals(0, histogram.getCount());
+    }
+
+    private static void assertNear(long expected, long actual)
+    {
+        assertThat(actual, allOf(greaterThanOrEqualTo(expected), lessThanOrEqualTo(expected + expected / 16)));
+    }
+
+    private static class ManualHistogramStatistic extends HistogramStatistic
+    {
+        private long now;
+
+        private ManualHistogramStatistic(long window, TimeUnit units, int slots)
+        {
+            super(window, units, slots);
+        }
+
+        private void advance(long time, TimeUnit units)
+        {
+            now += units.toNanos(time);
+        }
+
+        @Override
+        protected long now()
+        {
+            return now;
+        }
+    }
+}
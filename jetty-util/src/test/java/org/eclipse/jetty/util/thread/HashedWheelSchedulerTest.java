@@ -0,0 +1,146 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.thread;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.awaitility.Awaitility.await;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.sameInstance;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class HashedWheelSchedulerTest
+{
+    private HashedWheelScheduler scheduler;
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (scheduler != null)
+            scheduler.stop();
+    }
+
+    @Test
+    public void testCancelledTasksArePendingNoMore() throws Exception
+    {
+        scheduler = new HashedWheelScheduler();
+        scheduler.start();
+
+        List<Scheduler.Task> tasks = new ArrayList<>();
+        for (int i = 0; i < 10_000; ++i)
+        {
+            tasks.add(scheduler.schedule(() -> {}, 30, TimeUnit.SECONDS));
+        }
+        assertThat(scheduler.getPendingTasks(), is(10_000L));
+
+        tasks.forEach(task -> assertTrue(task.cancel()));
+        assertThat(scheduler.getPendingTasks(), is(0L));
+        // Cancelling twice has no effect.
+        assertFalse(tasks.get(0).cancel());
+        assertThat(scheduler.getPendingTasks(), is(0L));
+    }
+
+    @Test
+    public void testOverflowTasks() throws Exception
+    {
+        // The wheel revolution is 16 * 10 ms.
+        scheduler = new HashedWheelScheduler(null, false, 10, 10);
+        scheduler.start();
+        assertThat(scheduler.getWheelSize(), is(16));
+
+        CountDownLatch latch = new CountDownLatch(2);
+        long start = System.nanoTime();
+        scheduler.schedule(latch::countDown, 50, TimeUnit.MILLISECONDS);
+        scheduler.schedule(latch::countDown, 500, TimeUnit.MILLISECONDS);
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertThat(TimeUnit.NANOSECONDS.toMillis(System.nanoTime() - start), greaterThanOrEqualTo(500L));
+        assertThat(scheduler.getOverflowTasks(), is(1L));
+        assertThat(scheduler.getPendingTasks(), is(0L));
+    }
+
+    @Test
+    public void testLateTasks() throws Exception
+    {
+        scheduler = new HashedWheelScheduler();
+        scheduler.start();
+
+        // A blocking task delays the next one.
+        scheduler.schedule(() -> sleep(500), 0, TimeUnit.MILLISECONDS);
+        CountDownLatch latch = new CountDownLatch(1);
+        scheduler.schedule(latch::countDown, 100, TimeUnit.MILLISECONDS);
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertThat(scheduler.getLateTasks(), is(1L));
+        assertThat(scheduler.getMaxLateness(), greaterThanOrEqualTo(100L));
+    }
+
+    @Test
+    public void testComponentScheduler() throws Exception
+    {
+        scheduler = new HashedWheelScheduler();
+        scheduler.start();
+
+        HashedWheelScheduler.ComponentScheduler component = scheduler.getComponentScheduler("connector");
+        assertThat(scheduler.getComponentScheduler("connector"), sameInstance(component));
+
+        CountDownLatch latch = new CountDownLatch(1);
+        component.schedule(latch::countDown, 10, TimeUnit.MILLISECONDS);
+        Scheduler.Task task = component.schedule(() -> {}, 30, TimeUnit.SECONDS);
+        scheduler.schedule(() -> {}, 30, TimeUnit.SECONDS);
+        assertThat(component.getPendingTasks(), is(2L));
+        assertThat(scheduler.getPendingTasks(), is(3L));
+
+        assertTrue(latch.await(5, TimeUnit.SECONDS));
+        assertTrue(task.cancel());
+        await().atMost(5, TimeUnit.SECONDS).until(component::getExpiredTasks, is(1L));
+        assertThat(component.getScheduledTasks(), is(2L));
+        assertThat(component.getCancelledTasks(), is(1L));
+        assertThat(component.getPendingTasks(), is(0L));
+        assertThat(scheduler.getPendingTasks(), is(1L));
+    }
+
+    @Test
+    public void testStoppedScheduler() throws Exception
+    {
+        scheduler = new HashedWheelScheduler();
+        scheduler.start();
+        scheduler.schedule(() -> {}, 30, TimeUnit.SECONDS);
+        scheduler.stop();
+
+        assertThat(scheduler.getPendingTasks(), is(0L));
+        assertFalse(scheduler.schedule(() -> {}, 1, TimeUnit.SECONDS).cancel());
+    }
+
+    private static void sleep(long millis)
+    {
+        try
+        {
+            Thread.sleep(millis);
+        }
+        catch (InterruptedException x)
+        {
+            throw new RuntimeException(x);
+        }
+    }
+}
@@ -38,7 +38,8 @@ public class SchedulerTest
     {
         return Stream.of(
             TimerScheduler.class,
-            ScheduledExecutorScheduler.class
+            ScheduledExecutorScheduler.class,
+            HashedWheelScheduler.class
         );
     }
 
@@ -177,7 +178,7 @@ public class SchedulerTest
     public void testTaskThrowsException(Class<? extends Scheduler> impl) throws Exception
     {
         Scheduler scheduler = start(impl);
-        try (StacklessLogging ignore = new StacklessLogging(TimerScheduler.class))
+        try (StacklessLogging ignore = new StacklessLogging(TimerScheduler.class, HashedWheelScheduler.class))
         {
             long delay = 500;
             scheduler.schedule(new Runnable()
@@ -0,0 +1,128 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util.thread.strategy;
+
+import java.util.Queue;
+import java.util.concurrent.ConcurrentLinkedQueue;
+import java.util.concurrent.atomic.AtomicLong;
+
+import org.eclipse.jetty.util.thread.Invocable;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+
+public class AdaptiveExecutionStrategyTest
+{
+    private final Queue<Runnable> _tasks = new ConcurrentLinkedQueue<>();
+    private final Queue<Runnable> _executions = new ConcurrentLinkedQueue<>();
+    private AdaptiveExecutionStrategy _strategy;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        _strategy = new AdaptiveExecutionStrategy(_tasks::poll, _executions::add);
+        _strategy.start();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        _strategy.stop();
+    }
+
+    @Test
+    public void testBlockingDetectionDisabledByDefault()
+    {
+        assertThat(_strategy.getBlockingThreshold(), is(-1L));
+
+        AtomicLong runs = new AtomicLong();
+        _tasks.add(new SlowTask(runs, 50));
+        _tasks.add(new SlowTask(runs, 0));
+        _strategy.produce();
+
+        assertThat(runs.get(), is(2L));
+        assertThat(_strategy.getPCTasksConsumed(), is(2L));
+        assertThat(_strategy.getBlockingTasksDetected(), is(0L));
+        assertThat(_executions.size(), is(0));
+    }
+
+    @Test
+    public void testBlockingTaskDetected()
+    {
+        _strategy.setBlockingThreshold(10);
+
+        AtomicLong runs = new AtomicLong();
+        _tasks.add(new SlowTask(runs, 50));
+        _strategy.produce();
+
+        assertThat(runs.get(), is(1L));
+        assertThat(_strategy.getPCTasksConsumed(), is(1L));
+        assertThat(_strategy.getBlockingTasksDetected(), is(1L));
+        assertThat(_strategy.isBlockingTask(SlowTask.class), is(true));
+
+        // Tasks of the same class are now executed, since
+        // there is no pending producer to perform EPC.
+        _tasks.add(new SlowTask(runs, 0));
+        _strategy.produce();
+
+        assertThat(runs.get(), is(1L));
+        assertThat(_strategy.getPCTasksConsumed(), is(1L));
+        assertThat(_strategy.getPECTasksExecuted(), is(1L));
+        assertThat(_executions.size(), is(1));
+        _executions.poll().run();
+        assertThat(runs.get(), is(2L));
+
+        _strategy.resetBlockingTasks();
+        assertThat(_strategy.isBlockingTask(SlowTask.class), is(false));
+        _tasks.add(new SlowTask(runs, 0));
+        _strategy.produce();
+        assertThat(runs.get(), is(3L));
+        assertThat(_strategy.getPCTasksConsumed(), is(2L));
+    }
+
+    private static class SlowTask implements Runnable, Invocable
+    {
+        private final AtomicLong _runs;
+        private final long _sleep;
+
+        private SlowTask(AtomicLong runs, long sleep)
+        {
+            _runs = runs;
+            _sleep = sleep;
+        }
+
+        @Override
+        public void run()
+        {
+            try
+            {
+                Thread.sleep(_sleep);
+                _runs.incrementAndGet();
+            }
+            catch (InterruptedException x)
+            {
+                throw new RuntimeException(x);
+            }
+        }
+
+        @Override
+        public InvocationType getInvocationType()
+        {
+            return InvocationType.NON_BLOCKING;
+        }
+    }
+}
@@ -0,0 +1,37 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Validates the parameters and the JSON content of requests against an
+OpenAPI 3.x document or a JSON Schema, before handling the requests.
+Invalid requests are rejected with an application/problem+json response.
+
+[tags]
+server
+
+[depend]
+server
+
+[xml]
+etc/jetty-validation.xml
+
+[lib]
+lib/jetty-util-ajax-${jetty.version}.jar
+lib/jetty-validation-${jetty.version}.jar
+
+[ini-template]
+## The OpenAPI document, in JSON format, to validate requests against.
+# jetty.validation.openApi=etc/openapi.json
+
+## The JSON Schema to validate the JSON content of all requests against,
+## alternative to the OpenAPI document.
+# jetty.validation.schema=etc/schema.json
+
+## The max number of violations reported for a request.
+# jetty.validation.maxViolations=16
+
+## The max request content size in bytes, or -1 for unlimited.
+# jetty.validation.maxContentSize=-1
+
+## The max request content size in bytes buffered in memory,
+## larger content is spooled to a temporary file.
+# jetty.validation.maxMemorySize=65536
@@ -0,0 +1,935 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.math.BigDecimal;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.Collection;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.regex.Pattern;
+import java.util.regex.PatternSyntaxException;
+
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>A compiled JSON Schema.</p>
+ * <p>A JSON Schema is compiled from its JSON document via {@link #from(String)}
+ * or {@link #from(Object)}, and JSON documents are validated against the schema
+ * either incrementally, via {@link #newValidator()}, or from their Java
+ * representation, via {@link #validate(Object)}.</p>
+ * <p>The supported keywords are a subset of JSON Schema 2020-12 that covers
+ * the validation vocabulary commonly used by OpenAPI 3.x documents:</p>
+ * <ul>
+ * <li>{@code type}, {@code enum}, {@code const}</li>
+ * <li>{@code properties}, {@code patternProperties}, {@code additionalProperties},
+ * {@code required}, {@code minProperties}, {@code maxProperties}</li>
+ * <li>{@code prefixItems}, {@code items}, {@code minItems}, {@code maxItems}</li>
+ * <li>{@code minLength}, {@code maxLength}, {@code pattern}</li>
+ * <li>{@code minimum}, {@code maximum}, {@code exclusiveMinimum},
+ * {@code exclusiveMaximum}, {@code multipleOf}</li>
+ * <li>{@code allOf}, {@code anyOf}, {@code oneOf}, {@code not}</li>
+ * <li>{@code $ref}, limited to references within the same document,
+ * such as {@code #/$defs/address} or {@code #/components/schemas/Pet}</li>
+ * </ul>
+ * <p>For compatibility with OpenAPI 3.0, the {@code nullable} keyword and the
+ * boolean form of {@code exclusiveMinimum} and {@code exclusiveMaximum} are
+ * also supported.
+ * Other keywords, such as {@code format}, are annotations and are ignored.
+ * Since validation is incremental, {@code enum} and {@code const} only
+ * compare scalar values.</p>
+ */
+public class JsonSchema
+{
+    private static final int MAX_SCALE = 1024;
+    private static final JsonSchema TRUE = new JsonSchema(true);
+    private static final JsonSchema FALSE = new JsonSchema(false);
+
+    private final Boolean _constant;
+    private Set<String> _types;
+    private List<Object> _enum;
+    private boolean _hasConst;
+    private Object _const;
+    private Map<String, JsonSchema> _properties = Map.of();
+    private Map<Pattern, JsonSchema> _patternProperties = Map.of();
+    private JsonSchema _additionalProperties;
+    private Set<String> _required = Set.of();
+    private int _minProperties;
+    private int _maxProperties = -1;
+    private List<JsonSchema> _prefixItems = List.of();
+    private JsonSchema _items;
+    private int _minItems;
+    private int _maxItems = -1;
+    private int _minLength;
+    private int _maxLength = -1;
+    private Pattern _pattern;
+    private BigDecimal _minimum;
+    private BigDecimal _maximum;
+    private BigDecimal _exclusiveMinimum;
+    private BigDecimal _exclusiveMaximum;
+    private BigDecimal _multipleOf;
+    private List<JsonSchema> _allOf = List.of();
+    private List<JsonSchema> _anyOf = List.of();
+    private List<JsonSchema> _oneOf = List.of();
+    private JsonSchema _not;
+
+    private JsonSchema(Boolean constant)
+    {
+        _constant = constant;
+    }
+
+    /**
+     * @param json the JSON Schema document
+     * @return the compiled JSON Schema
+     * @throws IllegalArgumentException if the document is not a valid JSON Schema
+     */
+    public static JsonSchema from(String json)
+    {
+        return from(new JSON().fromJSON(json));
+    }
+
+    /**
+     * @param document the JSON Schema document, as parsed by {@link JSON}, or a {@code Boolean}
+     * @return the compiled JSON Schema
+     * @throws IllegalArgumentException if the document is not a valid JSON Schema
+     */
+    public static JsonSchema from(Object document)
+    {
+        return new Compiler(document).compile(document, "#");
+    }
+
+    /**
+     * @return a new incremental validator for a JSON document
+     */
+    public JsonValidator newValidator()
+    {
+        return new JsonValidator(this);
+    }
+
+    /**
+     * <p>Validates the given value against this schema.</p>
+     * <p>The value is the Java representation of a JSON value, either {@code null},
+     * a {@code Boolean}, a {@code Number}, a {@code String}, a {@code Map},
+     * a {@code Collection} or an array.</p>
+     *
+     * @param value the value to validate
+     * @return the list of violations, empty if the value is valid
+     */
+    public List<Violation> validate(Object value)
+    {
+        return validate(value, null);
+    }
+
+    List<Violation> validate(Object value, String parameter)
+    {
+        Violations violations = new Violations(JsonValidator.DEFAULT_MAX_VIOLATIONS, parameter);
+        emit(newValidation("", violations), value);
+        return violations.getViolations();
+    }
+
+    private static void emit(Validation validation, Object value)
+    {
+        if (value instanceof Map)
+        {
+            validation.startObject();
+            for (Map.Entry<?, ?> entry : ((Map<?, ?>)value).entrySet())
+            {
+                emit(validation.field(String.valueOf(entry.getKey())), entry.getValue());
+            }
+        }
+        else if (value instanceof Collection || value instanceof Object[])
+        {
+            validation.startArray();
+            int index = 0;
+            for (Object item : asList(value))
+            {
+                emit(validation.item(index++), item);
+            }
+        }
+        else
+        {
+            validation.value(normalize(value));
+        }
+        validation.end();
+    }
+
+    Validation newValidation(String pointer, Violations violations)
+    {
+        if (this == TRUE)
+            return Validation.NOOP;
+        return new SchemaValidation(this, pointer, violations);
+    }
+
+    Set<String> getTypes()
+    {
+        return _types == null ? Set.of() : _types;
+    }
+
+    JsonSchema getItems()
+    {
+        return _items;
+    }
+
+    private void init(Map<?, ?> node, String location, Compiler compiler)
+    {
+        Object type = node.get("type");
+        if (type != null)
+        {
+            _types = new LinkedHashSet<>();
+            for (Object item : type instanceof String ? List.of(type) : asList(type, location + "/type"))
+            {
+                _types.add(String.valueOf(item));
+            }
+            if (Boolean.TRUE.equals(node.get("nullable")))
+                _types.add("null");
+        }
+
+        Object enumeration = node.get("enum");
+        if (enumeration != null)
+        {
+            _enum = new ArrayList<>();
+            for (Object item : asList(enumeration, location + "/enum"))
+            {
+                _enum.add(normalize(item));
+            }
+        }
+
+        if (node.containsKey("const"))
+        {
+            _hasConst = true;
+            _const = normalize(node.get("const"));
+        }
+
+        Object properties = node.get("properties");
+        if (properties != null)
+        {
+            _properties = new LinkedHashMap<>();
+            for (Map.Entry<?, ?> entry : asMap(properties, location + "/properties").entrySet())
+            {
+                String name = String.valueOf(entry.getKey());
+                _properties.put(name, compiler.compile(entry.getValue(), location + "/properties/" + JsonValidator.escape(name)));
+            }
+        }
+
+        Object patternProperties = node.get("patternProperties");
+        if (patternProperties != null)
+        {
+            _patternProperties = new LinkedHashMap<>();
+            for (Map.Entry<?, ?> entry : asMap(patternProperties, location + "/patternProperties").entrySet())
+            {
+                String regex = String.valueOf(entry.getKey());
+                String path = location + "/patternProperties/" + JsonValidator.escape(regex);
+                _patternProperties.put(pattern(regex, path), compiler.compile(entry.getValue(), path));
+            }
+        }
+
+        if (node.containsKey("additionalProperties"))
+            _additionalProperties = compiler.compile(node.get("additionalProperties"), location + "/additionalProperties");
+
+        Object required = node.get("required");
+        if (required != null)
+        {
+            _required = new LinkedHashSet<>();
+            for (Object item : asList(required, location + "/required"))
+            {
+                _required.add(String.valueOf(item));
+            }
+        }
+
+        _minProperties = integer(node, "minProperties", 0, location);
+        _maxProperties = integer(node, "maxProperties", -1, location);
+
+        Object prefixItems = node.get("prefixItems");
+        Object items = node.get("items");
+        if (items instanceof Collection || items instanceof Object[])
+        {
+            // Drafts before 2020-12 define tuples with an array of schemas.
+            prefixItems = items;
+            items = node.get("additionalItems");
+        }
+        if (prefixItems != null)
+        {
+            _prefixItems = new ArrayList<>();
+            int index = 0;
+            for (Object item : asList(prefixItems, location + "/prefixItems"))
+            {
+                _prefixItems.add(compiler.compile(item, location + "/prefixItems/" + index++));
+            }
+        }
+        if (items != null)
+            _items = compiler.compile(items, location + "/items");
+        _minItems = integer(node, "minItems", 0, location);
+        _maxItems = integer(node, "maxItems", -1, location);
+
+        _minLength = integer(node, "minLength", 0, location);
+        _maxLength = integer(node, "maxLength", -1, location);
+        Object pattern = node.get("pattern");
+        if (pattern != null)
+            _pattern = pattern(String.valueOf(pattern), location + "/pattern");
+
+        _minimum = number(node, "minimum", location);
+        _maximum = number(node, "maximum", location);
+        Object exclusiveMinimum = node.get("exclusiveMinimum");
+        if (exclusiveMinimum instanceof Boolean)
+        {
+            // OpenAPI 3.0 and JSON Schema draft 4.
+            if ((Boolean)exclusiveMinimum)
+            {
+                _exclusiveMinimum = _minimum;
+                _minimum = null;
+            }
+        }
+        else
+        {
+            _exclusiveMinimum = number(node, "exclusiveMinimum", location);
+        }
+        Object exclusiveMaximum = node.get("exclusiveMaximum");
+        if (exclusiveMaximum instanceof Boolean)
+        {
+            if ((Boolean)exclusiveMaximum)
+            {
+                _exclusiveMaximum = _maximum;
+                _maximum = null;
+            }
+        }
+        else
+        {
+            _exclusiveMaximum = number(node, "exclusiveMaximum", location);
+        }
+        _multipleOf = number(node, "multipleOf", location);
+        if (_multipleOf != null && _multipleOf.signum() <= 0)
+            throw new IllegalArgumentException("Invalid multipleOf at " + location);
+
+        _allOf = schemas(node, "allOf", location, compiler);
+        _anyOf = schemas(node, "anyOf", location, compiler);
+        _oneOf = schemas(node, "oneOf", location, compiler);
+        if (node.containsKey("not"))
+            _not = compiler.compile(node.get("not"), location + "/not");
+
+        Object ref = node.get("$ref");
+        if (ref != null)
+        {
+            // Since 2019-09, $ref can be used with sibling keywords,
+            // so it is equivalent to an additional allOf subschema.
+            List<JsonSchema> allOf = new ArrayList<>(_allOf);
+            allOf.add(compiler.resolve(String.valueOf(ref), location));
+            _allOf = allOf;
+        }
+    }
+
+    private static List<JsonSchema> schemas(Map<?, ?> node, String keyword, String location, Compiler compiler)
+    {
+        Object value = node.get(keyword);
+        if (value == null)
+            return List.of();
+        List<JsonSchema> result = new ArrayList<>();
+        int index = 0;
+        for (Object item : asList(value, location + "/" + keyword))
+        {
+            result.add(compiler.compile(item, location + "/" + keyword + "/" + index++));
+        }
+        if (result.isEmpty())
+            throw new IllegalArgumentException("Empty " + keyword + " at " + location);
+        return result;
+    }
+
+    private static int integer(Map<?, ?> node, String keyword, int defaultValue, String location)
+    {
+        Object value = node.get(keyword);
+        if (value == null)
+            return defaultValue;
+        if (!(value instanceof Number) || ((Number)value).intValue() < 0)
+            throw new IllegalArgumentException("Invalid " + keyword + " at " + location);
+        return ((Number)value).intValue();
+    }
+
+    private static BigDecimal number(Map<?, ?> node, String keyword, String location)
+    {
+        Object value = node.get(keyword);
+        if (value == null)
+            return null;
+        if (!(value instanceof Number))
+            throw new IllegalArgumentException("Invalid " + keyword + " at " + location);
+        return new BigDecimal(value.toString());
+    }
+
+    private static Pattern pattern(String regex, String location)
+    {
+        try
+        {
+            return Pattern.compile(regex);
+        }
+        catch (PatternSyntaxException x)
+        {
+            throw new IllegalArgumentException("Invalid pattern at " + location, x);
+        }
+    }
+
+    static Object normalize(Object value)
+    {
+        if (value instanceof BigDecimal)
+            return value;
+        if (value instanceof Number)
+            return new BigDecimal(value.toString());
+        return value;
+    }
+
+    static List<?> asList(Object value)
+    {
+        if (value instanceof Object[])
+            return Arrays.asList((Object[])value);
+        return new ArrayList<>((Collection<?>)value);
+    }
+
+    private static List<?> asList(Object value, String location)
+    {
+        if (value instanceof Collection || value instanceof Object[])
+            return asList(value);
+        throw new IllegalArgumentException("Expected array at " + location);
+    }
+
+    private static Map<?, ?> asMap(Object value, String location)
+    {
+        if (value instanceof Map)
+            return (Map<?, ?>)value;
+        throw new IllegalArgumentException("Expected object at " + location);
+    }
+
+    @Override
+    public String toString()
+    {
+        if (_constant != null)
+            return String.format("%s@%x[%b]", getClass().getSimpleName(), hashCode(), _constant);
+        return String.format("%s@%x[types=%s,properties=%s]", getClass().getSimpleName(), hashCode(), _types, _properties.keySet());
+    }
+
+    /**
+     * <p>Compiles the schemas of a document, resolving the
+     * {@code $ref} references against the document root.</p>
+     */
+    static class Compiler
+    {
+        private final Map<String, JsonSchema> _refs = new HashMap<>();
+        private final Object _root;
+
+        Compiler(Object root)
+        {
+            _root = root;
+        }
+
+        JsonSchema compile(Object node, String location)
+        {
+            if (node instanceof Boolean)
+                return (Boolean)node ? TRUE : FALSE;
+            JsonSchema schema = new JsonSchema(null);
+            schema.init(asMap(node, location), location, this);
+            return schema;
+        }
+
+        JsonSchema resolve(String ref, String location)
+        {
+            JsonSchema schema = _refs.get(ref);
+            if (schema != null)
+                return schema;
+
+            Object node = lookup(ref, location);
+            if (node instanceof Boolean)
+                return compile(node, ref);
+
+            // Register the schema before initializing it, to support recursive references.
+            schema = new JsonSchema(null);
+            _refs.put(ref, schema);
+            schema.init(asMap(node, ref), ref, this);
+            return schema;
+        }
+
+        Object lookup(String ref, String location)
+        {
+            if (!ref.startsWith("#"))
+                throw new IllegalArgumentException("Unsupported $ref " + ref + " at " + location + ", only references within the same document are supported");
+            String pointer = ref.substring(1);
+            Object node = _root;
+            if (pointer.isEmpty())
+                return node;
+            if (!pointer.startsWith("/"))
+                throw new IllegalArgumentException("Unsupported $ref " + ref + " at " + location);
+            for (String token : pointer.substring(1).split("/", -1))
+            {
+                String name = JsonValidator.unescape(token);
+                if (node instanceof Map)
+                {
+                    node = ((Map<?, ?>)node).get(name);
+                }
+                else if (node instanceof Collection || node instanceof Object[])
+                {
+                    List<?> list = asList(node);
+                    try
+                    {
+                        int index = Integer.parseInt(name);
+                        node = index >= 0 && index < list.size() ? list.get(index) : null;
+                    }
+                    catch (NumberFormatException x)
+                    {
+                        node = null;
+                    }
+                }
+                else
+                {
+                    node = null;
+                }
+                if (node == null)
+                    throw new IllegalArgumentException("Unresolvable $ref " + ref + " at " + location);
+            }
+            return node;
+        }
+    }
+
+    /**
+     * <p>Collects the violations of a validation.</p>
+     */
+    static class Violations
+    {
+        private final List<Violation> _violations = new ArrayList<>();
+        private final int _maxViolations;
+        private final String _parameter;
+        private int _count;
+
+        Violations(int maxViolations, String parameter)
+        {
+            _maxViolations = maxViolations;
+            _parameter = parameter;
+        }
+
+        void add(String pointer, String message)
+        {
+            ++_count;
+            if (_violations.size() < _maxViolations)
+                _violations.add(new Violation(pointer, _parameter, message));
+        }
+
+        boolean isEmpty()
+        {
+            return _count == 0;
+        }
+
+        List<Violation> getViolations()
+        {
+            return _violations;
+        }
+    }
+
+    /**
+     * <p>The validation of a single JSON value, notified of the
+     * JSON events of the value as they are parsed.</p>
+     * <p>Scalar values are notified via {@link #value(Object)}, objects
+     * via {@link #startObject()} followed by a {@link #field(String)}
+     * for each field, and arrays via {@link #startArray()} followed by
+     * an {@link #item(int)} for each item; {@link #end()} is always
+     * called when the value is complete.</p>
+     */
+    abstract static class Validation
+    {
+        static final Validation NOOP = new Validation()
+        {
+            @Override
+            void value(Object value)
+            {
+            }
+
+            @Override
+            void startObject()
+            {
+            }
+
+            @Override
+            Validation field(String name)
+            {
+                return this;
+            }
+
+            @Override
+            void startArray()
+            {
+            }
+
+            @Override
+            Validation item(int index)
+            {
+                return this;
+            }
+
+            @Override
+            void end()
+            {
+            }
+        };
+
+        /**
+         * @param value a scalar value, either {@code null}, a {@code Boolean},
+         * a {@code String} or a {@code BigDecimal}
+         */
+        abstract void value(Object value);
+
+        abstract void startObject();
+
+        /**
+         * @param name the field name
+         * @return the validation of the field value
+         */
+        abstract Validation field(String name);
+
+        abstract void startArray();
+
+        /**
+         * @param index the item index
+         * @return the validation of the item value
+         */
+        abstract Validation item(int index);
+
+        abstract void end();
+
+        static Validation of(List<Validation> validations)
+        {
+            validations.removeIf(validation -> validation == NOOP);
+            switch (validations.size())
+            {
+                case 0:
+                    return NOOP;
+                case 1:
+                    return validations.get(0);
+                default:
+                    return new Fanout(validations);
+            }
+        }
+    }
+
+    private static class Fanout extends Validation
+    {
+        private final List<Validation> _validations;
+
+        private Fanout(List<Validation> validations)
+        {
+            _validations = validations;
+        }
+
+        @Override
+        void value(Object value)
+        {
+            _validations.forEach(validation -> validation.value(value));
+        }
+
+        @Override
+        void startObject()
+        {
+            _validations.forEach(Validation::startObject);
+        }
+
+        @Override
+        Validation field(String name)
+        {
+            List<Validation> children = new ArrayList<>(_validations.size());
+            _validations.forEach(validation -> children.add(validation.field(name)));
+            return of(children);
+        }
+
+        @Override
+        void startArray()
+        {
+            _validations.forEach(Validation::startArray);
+        }
+
+        @Override
+        Validation item(int index)
+        {
+            List<Validation> children = new ArrayList<>(_validations.size());
+            _validations.forEach(validation -> children.add(validation.item(index)));
+            return of(children);
+        }
+
+        @Override
+        void end()
+        {
+            _validations.forEach(Validation::end);
+        }
+    }
+
+    private static class SchemaValidation extends Validation
+    {
+        private final List<Validation> _delegates = new ArrayList<>();
+        private final List<Violations> _anyOf;
+        private final List<Violations> _oneOf;
+        private final Violations _not;
+        private final JsonSchema _schema;
+        private final String _pointer;
+        private final Violations _violations;
+        private Set<String> _names;
+        private String _kind;
+        private int _count;
+
+        private SchemaValidation(JsonSchema schema, String pointer, Violations violations)
+        {
+            _schema = schema;
+            _pointer = pointer;
+            _violations = violations;
+            for (JsonSchema allOf : schema._allOf)
+            {
+                _delegates.add(allOf.newValidation(pointer, violations));
+            }
+            _anyOf = branches(schema._anyOf);
+            _oneOf = branches(schema._oneOf);
+            _not = schema._not == null ? null : branch(schema._not);
+        }
+
+        private List<Violations> branches(List<JsonSchema> schemas)
+        {
+            if (schemas.isEmpty())
+                return List.of();
+            List<Violations> result = new ArrayList<>(schemas.size());
+            for (JsonSchema schema : schemas)
+            {
+                result.add(branch(schema));
+            }
+            return result;
+        }
+
+        private Violations branch(JsonSchema schema)
+        {
+            // Branches only need to know whether they are valid.
+            Violations violations = new Violations(0, null);
+            _delegates.add(schema.newValidation(_pointer, violations));
+            return violations;
+        }
+
+        @Override
+        void value(Object value)
+        {
+            String type = typeOf(value);
+            if (!start(type))
+                return;
+
+            if (_schema._enum != null && _schema._enum.stream().noneMatch(item -> same(item, value)))
+                _violations.add(_pointer, "value must be one of " + _schema._enum);
+            if (_schema._hasConst && !same(_schema._const, value))
+                _violations.add(_pointer, "value must be " + _schema._const);
+
+            if (value instanceof String)
+            {
+                String string = (String)value;
+                int length = string.codePointCount(0, string.length());
+                if (length < _schema._minLength)
+                    _violations.add(_pointer, "string must be at least " + _schema._minLength + " characters long");
+                if (_schema._maxLength >= 0 && length > _schema._maxLength)
+                    _violations.add(_pointer, "string must be at most " + _schema._maxLength + " characters long");
+                if (_schema._pattern != null && !_schema._pattern.matcher(string).find())
+                    _violations.add(_pointer, "string must match pattern " + _schema._pattern.pattern());
+            }
+            else if (value instanceof BigDecimal)
+            {
+                BigDecimal number = (BigDecimal)value;
+                // Avoid expensive arithmetic on numbers such as 1e999999999.
+                if (Math.abs(number.scale()) > MAX_SCALE)
+                {
+                    _violations.add(_pointer, "number out of range");
+                    return;
+                }
+                if (_schema._minimum != null && number.compareTo(_schema._minimum) < 0)
+                    _violations.add(_pointer, "number must be greater than or equal to " + _schema._minimum);
+                if (_schema._maximum != null && number.compareTo(_schema._maximum) > 0)
+                    _violations.add(_pointer, "number must be less than or equal to " + _schema._maximum);
+                if (_schema._exclusiveMinimum != null && number.compareTo(_schema._exclusiveMinimum) <= 0)
+                    _violations.add(_pointer, "number must be greater than " + _schema._exclusiveMinimum);
+                if (_schema._exclusiveMaximum != null && number.compareTo(_schema._exclusiveMaximum) >= 0)
+                    _violations.add(_pointer, "number must be less than " + _schema._exclusiveMaximum);
+                if (_schema._multipleOf != null && number.remainder(_schema._multipleOf).signum() != 0)
+                    _violations.add(_pointer, "number must be a multiple of " + _schema._multipleOf);
+            }
+
+            _delegates.forEach(validation -> validation.value(value));
+        }
+
+        @Override
+        void startObject()
+        {
+            if (!start("object"))
+                return;
+            if (!_schema._required.isEmpty())
+                _names = new HashSet<>();
+            _delegates.forEach(Validation::startObject);
+        }
+
+        @Override
+        Validation field(String name)
+        {
+            if (_kind == null)
+                return NOOP;
+
+            ++_count;
+            if (_names != null)
+                _names.add(name);
+
+            String pointer = _pointer + "/" + JsonValidator.escape(name);
+            List<Validation> children = new ArrayList<>();
+            boolean matched = false;
+            JsonSchema property = _schema._properties.get(name);
+            if (property != null)
+            {
+                matched = true;
+                children.add(property.newValidation(pointer, _violations));
+            }
+            for (Map.Entry<Pattern, JsonSchema> entry : _schema._patternProperties.entrySet())
+            {
+                if (entry.getKey().matcher(name).find())
+                {
+                    matched = true;
+                    children.add(entry.getValue().newValidation(pointer, _violations));
+                }
+            }
+            if (!matched && _schema._additionalProperties != null)
+            {
+                if (_schema._additionalProperties == FALSE)
+                    _violations.add(pointer, "additional property \"" + name + "\" is not allowed");
+                else
+                    children.add(_schema._additionalProperties.newValidation(pointer, _violations));
+            }
+            for (Validation delegate : _delegates)
+            {
+                children.add(delegate.field(name));
+            }
+            return of(children);
+        }
+
+        @Override
+        void startArray()
+        {
+            if (!start("array"))
+                return;
+            _delegates.forEach(Validation::startArray);
+        }
+
+        @Override
+        Validation item(int index)
+        {
+            if (_kind == null)
+                return NOOP;
+
+            ++_count;
+            String pointer = _pointer + "/" + index;
+            List<Validation> children = new ArrayList<>();
+            JsonSchema item = index < _schema._prefixItems.size() ? _schema._prefixItems.get(index) : _schema._items;
+            if (item == FALSE)
+                _violations.add(pointer, "additional item is not allowed");
+            else if (item != null)
+                children.add(item.newValidation(pointer, _violations));
+            for (Validation delegate : _delegates)
+            {
+                children.add(delegate.item(index));
+            }
+            return of(children);
+        }
+
+        @Override
+        void end()
+        {
+            if (_kind == null)
+                return;
+
+            if ("object".equals(_kind))
+            {
+                for (String name : _schema._required)
+                {
+                    if (!_names.contains(name))
+                        _violations.add(_pointer, "missing required property \"" + name + "\"");
+                }
+                if (_count < _schema._minProperties)
+                    _violations.add(_pointer, "object must have at least " + _schema._minProperties + " properties");
+                if (_schema._maxProperties >= 0 && _count > _schema._maxProperties)
+                    _violations.add(_pointer, "object must have at most " + _schema._maxProperties + " properties");
+            }
+            else if ("array".equals(_kind))
+            {
+                if (_count < _schema._minItems)
+                    _violations.add(_pointer, "array must have at least " + _schema._minItems + " items");
+                if (_schema._maxItems >= 0 && _count > _schema._maxItems)
+                    _violations.add(_pointer, "array must have at most " + _schema._maxItems + " items");
+            }
+
+            _delegates.forEach(Validation::end);
+
+            if (!_anyOf.isEmpty() && _anyOf.stream().noneMatch(Violations::isEmpty))
+                _violations.add(_pointer, "value must match at least one of the anyOf schemas");
+            if (!_oneOf.isEmpty())
+            {
+                long valid = _oneOf.stream().filter(Violations::isEmpty).count();
+                if (valid != 1)
+                    _violations.add(_pointer, "value must match exactly one of the oneOf schemas, but matched " + valid);
+            }
+            if (_not != null && _not.isEmpty())
+                _violations.add(_pointer, "value must not match the not schema");
+        }
+
+        /**
+         * @param type the JSON type of the value
+         * @return whether the value should be further validated
+         */
+        private boolean start(String type)
+        {
+            if (_schema == FALSE)
+            {
+                _violations.add(_pointer, "no value is allowed");
+                return false;
+            }
+            _kind = type;
+            Set<String> types = _schema._types;
+            if (types != null && !types.contains(type) && !("integer".equals(type) && types.contains("number")))
+                _violations.add(_pointer, "expected " + String.join(" or ", types) + " but found " + type);
+            if (("object".equals(type) || "array".equals(type)))
+            {
+                // Only scalar values can be compared incrementally.
+                if (_schema._enum != null && _schema._enum.stream().noneMatch(item -> item instanceof Map || item instanceof Collection || item instanceof Object[]))
+                    _violations.add(_pointer, "value must be one of " + _schema._enum);
+                if (_schema._hasConst && !(_schema._const instanceof Map || _schema._const instanceof Collection || _schema._const instanceof Object[]))
+                    _violations.add(_pointer, "value must be " + _schema._const);
+            }
+            return true;
+        }
+
+        private static String typeOf(Object value)
+        {
+            if (value == null)
+                return "null";
+            if (value instanceof Boolean)
+                return "boolean";
+            if (value instanceof BigDecimal)
+            {
+                BigDecimal number = (BigDecimal)value;
+                return number.signum() == 0 || number.stripTrailingZeros().scale() <= 0 ? "integer" : "number";
+            }
+            return "string";
+        }
+
+        private static boolean same(Object expected, Object actual)
+        {
+            if (expected instanceof BigDecimal && actual instanceof BigDecimal)
+                return ((BigDecimal)expected).compareTo((BigDecimal)actual) == 0;
+            return Objects.equals(expected, actual);
+        }
+    }
+}
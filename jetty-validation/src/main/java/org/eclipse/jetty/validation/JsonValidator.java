@@ -0,0 +1,586 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.math.BigDecimal;
+import java.nio.ByteBuffer;
+import java.util.ArrayDeque;
+import java.util.Deque;
+import java.util.List;
+import java.util.regex.Pattern;
+
+import org.eclipse.jetty.util.Utf8Appendable;
+import org.eclipse.jetty.util.Utf8StringBuilder;
+
+/**
+ * <p>Validates a UTF-8 JSON document against a {@link JsonSchema}
+ * incrementally, as the document bytes are {@link #parse(ByteBuffer) parsed}.</p>
+ * <p>The document is never fully held in memory: only the current string or
+ * number token and the stack of the enclosing objects and arrays are retained,
+ * so that large documents can be validated while they are received, and
+ * invalid documents can be rejected as soon as a violation is detected.</p>
+ * <p>Typical usage:</p>
+ * <pre>
+ * JsonValidator validator = schema.newValidator();
+ * while (hasMoreContent())
+ * {
+ *     validator.parse(nextChunk());
+ *     if (!validator.isValid())
+ *         reject(validator.getViolations());
+ * }
+ * validator.complete();
+ * if (!validator.isValid())
+ *     reject(validator.getViolations());
+ * </pre>
+ * <p>Instances are not thread-safe.</p>
+ */
+public class JsonValidator
+{
+    static final int DEFAULT_MAX_VIOLATIONS = 16;
+    private static final Pattern NUMBER = Pattern.compile("-?(0|[1-9][0-9]*)(\\.[0-9]+)?([eE][+-]?[0-9]+)?");
+
+    private final Deque<Frame> _stack = new ArrayDeque<>();
+    private final Utf8StringBuilder _string = new Utf8StringBuilder();
+    private final StringBuilder _token = new StringBuilder();
+    private final JsonSchema.Violations _violations;
+    private int _maxDepth = 64;
+    private int _maxStringLength = 1024 * 1024;
+    private Token _tokenType;
+    private JsonSchema.Validation _tokenValidation;
+    private boolean _fieldName;
+    private boolean _escape;
+    private int _unicode = -1;
+    private long _offset;
+    private boolean _failed;
+    private boolean _complete;
+
+    JsonValidator(JsonSchema schema)
+    {
+        this(schema, DEFAULT_MAX_VIOLATIONS);
+    }
+
+    JsonValidator(JsonSchema schema, int maxViolations)
+    {
+        _violations = new JsonSchema.Violations(maxViolations, null);
+        _stack.push(new Frame(Kind.ROOT, schema.newValidation("", _violations), State.VALUE));
+    }
+
+    /**
+     * @return the max nesting depth of objects and arrays
+     */
+    public int getMaxDepth()
+    {
+        return _maxDepth;
+    }
+
+    public void setMaxDepth(int maxDepth)
+    {
+        _maxDepth = maxDepth;
+    }
+
+    /**
+     * @return the max length of strings, in characters
+     */
+    public int getMaxStringLength()
+    {
+        return _maxStringLength;
+    }
+
+    public void setMaxStringLength(int maxStringLength)
+    {
+        _maxStringLength = maxStringLength;
+    }
+
+    /**
+     * @return whether no violation has been detected so far
+     */
+    public boolean isValid()
+    {
+        return _violations.isEmpty();
+    }
+
+    /**
+     * @return whether the document is not well-formed JSON
+     */
+    public boolean isMalformed()
+    {
+        return _failed;
+    }
+
+    /**
+     * @return the violations detected so far
+     */
+    public List<Violation> getViolations()
+    {
+        return _violations.getViolations();
+    }
+
+    /**
+     * @param bytes the document bytes to parse
+     * @param offset the offset of the document bytes
+     * @param length the number of document bytes
+     * @see #parse(ByteBuffer)
+     */
+    public void parse(byte[] bytes, int offset, int length)
+    {
+        parse(ByteBuffer.wrap(bytes, offset, length));
+    }
+
+    /**
+     * <p>Parses and validates the given document bytes.</p>
+     * <p>Parsing stops at the first syntax error.</p>
+     *
+     * @param buffer the document bytes to parse
+     */
+    public void parse(ByteBuffer buffer)
+    {
+        if (_complete)
+            throw new IllegalStateException("Complete");
+        while (!_failed && buffer.hasRemaining())
+        {
+            byte b = buffer.get();
+            ++_offset;
+            try
+            {
+                parse(b);
+            }
+            catch (Utf8Appendable.NotUtf8Exception x)
+            {
+                fail("invalid UTF-8 string");
+            }
+        }
+    }
+
+    /**
+     * <p>Signals the end of the document, reporting a
+     * violation if the document is incomplete.</p>
+     */
+    public void complete()
+    {
+        if (_complete)
+            return;
+        _complete = true;
+        if (_failed)
+            return;
+        if (_tokenType == Token.NUMBER)
+            endNumber();
+        if (_failed)
+            return;
+        Frame frame = _stack.peek();
+        if (_tokenType != null || frame._kind != Kind.ROOT || frame._state != State.END)
+            fail("unexpected end of JSON document");
+    }
+
+    private void parse(byte b)
+    {
+        if (_tokenType != null && parseToken(b))
+            return;
+        if (_failed)
+            return;
+
+        Frame frame = _stack.peek();
+        if (isWhitespace(b))
+            return;
+
+        switch (frame._state)
+        {
+            case VALUE:
+            {
+                startValue(frame, b);
+                break;
+            }
+            case VALUE_OR_END:
+            {
+                if (b == ']')
+                    endContainer();
+                else
+                    startValue(frame, b);
+                break;
+            }
+            case FIELD_OR_END:
+            case FIELD:
+            {
+                if (b == '"')
+                {
+                    _fieldName = true;
+                    startString(null);
+                }
+                else if (b == '}' && frame._state == State.FIELD_OR_END)
+                {
+                    endContainer();
+                }
+                else
+                {
+                    fail("expected field name");
+                }
+                break;
+            }
+            case COLON:
+            {
+                if (b == ':')
+                    frame._state = State.VALUE;
+                else
+                    fail("expected ':'");
+                break;
+            }
+            case COMMA_OR_END:
+            {
+                if (b == ',')
+                    frame._state = frame._kind == Kind.OBJECT ? State.FIELD : State.VALUE;
+                else if (b == '}' && frame._kind == Kind.OBJECT || b == ']' && frame._kind == Kind.ARRAY)
+                    endContainer();
+                else
+                    fail("expected ',' or end of " + (frame._kind == Kind.OBJECT ? "object" : "array"));
+                break;
+            }
+            case END:
+            {
+                fail("unexpected content after JSON document");
+                break;
+            }
+            default:
+            {
+                throw new IllegalStateException();
+            }
+        }
+    }
+
+    private void startValue(Frame frame, byte b)
+    {
+        JsonSchema.Validation validation;
+        switch (frame._kind)
+        {
+            case ROOT:
+                validation = frame._validation;
+                frame._state = State.END;
+                break;
+            case OBJECT:
+                validation = frame._validation.field(frame._name);
+                frame._state = State.COMMA_OR_END;
+                break;
+            case ARRAY:
+                validation = frame._validation.item(frame._index++);
+                frame._state = State.COMMA_OR_END;
+                break;
+            default:
+                throw new IllegalStateException();
+        }
+
+        switch (b)
+        {
+            case '{':
+            {
+                if (_stack.size() > _maxDepth)
+                {
+                    fail("max nesting depth " + _maxDepth + " exceeded");
+                    return;
+                }
+                validation.startObject();
+                _stack.push(new Frame(Kind.OBJECT, validation, State.FIELD_OR_END));
+                break;
+            }
+            case '[':
+            {
+                if (_stack.size() > _maxDepth)
+                {
+                    fail("max nesting depth " + _maxDepth + " exceeded");
+                    return;
+                }
+                validation.startArray();
+                _stack.push(new Frame(Kind.ARRAY, validation, State.VALUE_OR_END));
+                break;
+            }
+            case '"':
+            {
+                startString(validation);
+                break;
+            }
+            case 't':
+            case 'f':
+            case 'n':
+            {
+                _tokenType = Token.LITERAL;
+                _tokenValidation = validation;
+                _token.setLength(0);
+                _token.append((char)b);
+                break;
+            }
+            default:
+            {
+                if (b == '-' || (b >= '0' && b <= '9'))
+                {
+                    _tokenType = Token.NUMBER;
+                    _tokenValidation = validation;
+                    _token.setLength(0);
+                    _token.append((char)b);
+                }
+                else
+                {
+                    fail("unexpected character");
+                }
+                break;
+            }
+        }
+    }
+
+    private void endContainer()
+    {
+        Frame frame = _stack.pop();
+        frame._validation.end();
+    }
+
+    private void startString(JsonSchema.Validation validation)
+    {
+        _tokenType = Token.STRING;
+        _tokenValidation = validation;
+        _string.reset();
+        _escape = false;
+        _unicode = -1;
+    }
+
+    /**
+     * @param b the byte to parse
+     * @return whether the byte has been consumed by the current token
+     */
+    private boolean parseToken(byte b)
+    {
+        switch (_tokenType)
+        {
+            case STRING:
+                parseString(b);
+                return true;
+            case NUMBER:
+                if (b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E' || (b >= '0' && b <= '9'))
+                {
+                    _token.append((char)b);
+                    if (_token.length() > _maxStringLength)
+                        fail("max number length " + _maxStringLength + " exceeded");
+                    return true;
+                }
+                endNumber();
+                return false;
+            case LITERAL:
+                parseLiteral(b);
+                return true;
+            default:
+                throw new IllegalStateException();
+        }
+    }
+
+    private void parseString(byte b)
+    {
+        if (_unicode >= 0)
+        {
+            int digit = Character.digit(b, 16);
+            if (digit < 0)
+            {
+                fail("invalid unicode escape");
+                return;
+            }
+            _unicode = (_unicode << 4) | digit;
+            _token.append((char)b);
+            if (_token.length() == 4)
+            {
+                _string.append((char)_unicode);
+                _unicode = -1;
+            }
+            return;
+        }
+
+        if (_escape)
+        {
+            _escape = false;
+            switch (b)
+            {
+                case '"':
+                case '\\':
+                case '/':
+                    _string.append((char)b);
+                    break;
+                case 'b':
+                    _string.append('\b');
+                    break;
+                case 'f':
+                    _string.append('\f');
+                    break;
+                case 'n':
+                    _string.append('\n');
+                    break;
+                case 'r':
+                    _string.append('\r');
+                    break;
+                case 't':
+                    _string.append('\t');
+                    break;
+                case 'u':
+                    _unicode = 0;
+                    _token.setLength(0);
+                    break;
+                default:
+                    fail("invalid escape");
+                    break;
+            }
+            return;
+        }
+
+        if (b == '"')
+        {
+            endString();
+        }
+        else if (b == '\\')
+        {
+            _escape = true;
+        }
+        else if (b >= 0 && b < 0x20)
+        {
+            fail("control character in string");
+        }
+        else
+        {
+            _string.append(b);
+            if (_string.length() > _maxStringLength)
+                fail("max string length " + _maxStringLength + " exceeded");
+        }
+    }
+
+    private void endString()
+    {
+        String value = _string.toString();
+        JsonSchema.Validation validation = _tokenValidation;
+        _tokenType = null;
+        _tokenValidation = null;
+        if (_fieldName)
+        {
+            _fieldName = false;
+            Frame frame = _stack.peek();
+            frame._name = value;
+            frame._state = State.COLON;
+        }
+        else
+        {
+            validation.value(value);
+            validation.end();
+        }
+    }
+
+    private void endNumber()
+    {
+        String token = _token.toString();
+        JsonSchema.Validation validation = _tokenValidation;
+        _tokenType = null;
+        _tokenValidation = null;
+        if (!NUMBER.matcher(token).matches())
+        {
+            fail("invalid number");
+            return;
+        }
+        validation.value(new BigDecimal(token));
+        validation.end();
+    }
+
+    private void parseLiteral(byte b)
+    {
+        _token.append((char)b);
+        String token = _token.toString();
+        Object value;
+        if ("true".startsWith(token) || "false".startsWith(token) || "null".startsWith(token))
+        {
+            switch (token)
+            {
+                case "true":
+                    value = Boolean.TRUE;
+                    break;
+                case "false":
+                    value = Boolean.FALSE;
+                    break;
+                case "null":
+                    value = null;
+                    break;
+                default:
+                    // Need more bytes.
+                    return;
+            }
+        }
+        else
+        {
+            fail("invalid literal");
+            return;
+        }
+        JsonSchema.Validation validation = _tokenValidation;
+        _tokenType = null;
+        _tokenValidation = null;
+        validation.value(value);
+        validation.end();
+    }
+
+    private void fail(String message)
+    {
+        _failed = true;
+        _tokenType = null;
+        _tokenValidation = null;
+        _violations.add("", "malformed JSON at offset " + _offset + ": " + message);
+    }
+
+    private static boolean isWhitespace(byte b)
+    {
+        return b == ' ' || b == '\t' || b == '\n' || b == '\r';
+    }
+
+    static String escape(String name)
+    {
+        return name.replace("~", "~0").replace("/", "~1");
+    }
+
+    static String unescape(String token)
+    {
+        return token.replace("~1", "/").replace("~0", "~");
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[offset=%d,depth=%d,valid=%b]", getClass().getSimpleName(), hashCode(), _offset, _stack.size() - 1, isValid());
+    }
+
+    private enum Kind
+    {
+        ROOT, OBJECT, ARRAY
+    }
+
+    private enum State
+    {
+        VALUE, VALUE_OR_END, FIELD_OR_END, FIELD, COLON, COMMA_OR_END, END
+    }
+
+    private enum Token
+    {
+        STRING, NUMBER, LITERAL
+    }
+
+    private static class Frame
+    {
+        private final Kind _kind;
+        private final JsonSchema.Validation _validation;
+        private State _state;
+        private String _name;
+        private int _index;
+
+        private Frame(Kind kind, JsonSchema.Validation validation, State state)
+        {
+            _kind = kind;
+            _validation = validation;
+            _state = state;
+        }
+    }
+}
@@ -0,0 +1,244 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.HashMap;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import javax.servlet.http.HttpServletRequest;
+
+import org.eclipse.jetty.http.pathmap.MappedResource;
+import org.eclipse.jetty.http.pathmap.MatchedResource;
+import org.eclipse.jetty.http.pathmap.PathMappings;
+import org.eclipse.jetty.http.pathmap.UriTemplatePathSpec;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.ajax.JSON;
+
+/**
+ * <p>A {@link RequestSchema.Resolver} that resolves the schema of requests
+ * from the operations of an OpenAPI 3.x document in JSON format.</p>
+ * <p>The paths of the document are matched against the request path within
+ * the context, so the document {@code servers} are ignored, and the path
+ * templates such as {@code /pets/{id}} are matched via {@link UriTemplatePathSpec}.
+ * For each operation, the path, query, header and cookie parameters are
+ * validated against their schemas, and the request body against the schema
+ * of its media type.
+ * Requests that do not match any operation of the document are not validated.</p>
+ * <p>References to components, such as {@code #/components/schemas/Pet}, are
+ * resolved within the document; references to other documents are not supported.</p>
+ */
+public class OpenApi implements RequestSchema.Resolver
+{
+    private static final List<String> METHODS = List.of("get", "put", "post", "delete", "options", "head", "patch", "trace");
+
+    private final PathMappings<Map<String, RequestSchema>> _operations = new PathMappings<>();
+    private final String _version;
+
+    private OpenApi(Map<?, ?> document)
+    {
+        Object version = document.get("openapi");
+        if (version == null || !version.toString().startsWith("3."))
+            throw new IllegalArgumentException("Unsupported OpenAPI version " + version);
+        _version = version.toString();
+
+        JsonSchema.Compiler compiler = new JsonSchema.Compiler(document);
+        Object paths = document.get("paths");
+        if (paths == null)
+            return;
+        for (Map.Entry<?, ?> entry : asMap(paths, "#/paths").entrySet())
+        {
+            String template = String.valueOf(entry.getKey());
+            String location = "#/paths/" + JsonValidator.escape(template);
+            Map<?, ?> pathItem = dereference(compiler, entry.getValue(), location);
+            UriTemplatePathSpec pathSpec = new UriTemplatePathSpec(template);
+            List<RequestSchema.Parameter> pathParameters = parameters(compiler, pathItem.get("parameters"), location + "/parameters");
+
+            Map<String, RequestSchema> operations = new HashMap<>();
+            for (String method : METHODS)
+            {
+                Object operation = pathItem.get(method);
+                if (operation == null)
+                    continue;
+                String operationLocation = location + "/" + method;
+                Map<?, ?> node = asMap(operation, operationLocation);
+                RequestSchema schema = operation(compiler, node, operationLocation, pathParameters);
+                schema.setPathSpec(pathSpec);
+                operations.put(StringUtil.asciiToUpperCase(method), schema);
+            }
+            _operations.put(pathSpec, operations);
+        }
+    }
+
+    /**
+     * @param json the OpenAPI document in JSON format
+     * @return the compiled OpenAPI document
+     * @throws IllegalArgumentException if the document is not a valid OpenAPI 3.x document
+     */
+    public static OpenApi from(String json)
+    {
+        return from(new JSON().fromJSON(json));
+    }
+
+    /**
+     * @param document the OpenAPI document, as parsed by {@link JSON}
+     * @return the compiled OpenAPI document
+     * @throws IllegalArgumentException if the document is not a valid OpenAPI 3.x document
+     */
+    public static OpenApi from(Object document)
+    {
+        return new OpenApi(asMap(document, "#"));
+    }
+
+    /**
+     * @return the OpenAPI version of the document
+     */
+    public String getVersion()
+    {
+        return _version;
+    }
+
+    /**
+     * @return the number of operations of the document
+     */
+    public int getOperationCount()
+    {
+        int count = 0;
+        for (MappedResource<Map<String, RequestSchema>> operations : _operations)
+        {
+            count += operations.getResource().size();
+        }
+        return count;
+    }
+
+    @Override
+    public RequestSchema resolve(HttpServletRequest request)
+    {
+        String path = Request.getBaseRequest(request).getPathInContext();
+        if (path == null)
+            return null;
+        MatchedResource<Map<String, RequestSchema>> matched = _operations.getMatched(path);
+        if (matched == null)
+            return null;
+        return matched.getResource().get(StringUtil.asciiToUpperCase(request.getMethod()));
+    }
+
+    private static RequestSchema operation(JsonSchema.Compiler compiler, Map<?, ?> node, String location, List<RequestSchema.Parameter> pathParameters)
+    {
+        Object operationId = node.get("operationId");
+        RequestSchema schema = new RequestSchema(operationId == null ? location : operationId.toString());
+
+        // Operation parameters override the path item parameters with the same name and location.
+        Map<String, RequestSchema.Parameter> parameters = new LinkedHashMap<>();
+        for (RequestSchema.Parameter parameter : pathParameters)
+        {
+            parameters.put(parameter.getLocation() + ":" + parameter.getName(), parameter);
+        }
+        for (RequestSchema.Parameter parameter : parameters(compiler, node.get("parameters"), location + "/parameters"))
+        {
+            parameters.put(parameter.getLocation() + ":" + parameter.getName(), parameter);
+        }
+        parameters.values().forEach(schema::addParameter);
+
+        Object requestBody = node.get("requestBody");
+        if (requestBody != null)
+        {
+            Map<?, ?> body = dereference(compiler, requestBody, location + "/requestBody");
+            schema.setBodyRequired(Boolean.TRUE.equals(body.get("required")));
+            Object content = body.get("content");
+            if (content != null)
+            {
+                for (Map.Entry<?, ?> entry : asMap(content, location + "/requestBody/content").entrySet())
+                {
+                    String mediaType = String.valueOf(entry.getKey());
+                    Map<?, ?> mediaTypeNode = asMap(entry.getValue(), location + "/requestBody/content/" + JsonValidator.escape(mediaType));
+                    Object schemaNode = mediaTypeNode.get("schema");
+                    JsonSchema jsonSchema = null;
+                    if (schemaNode != null && isJsonMediaType(mediaType))
+                        jsonSchema = compiler.compile(schemaNode, location + "/requestBody/content/" + JsonValidator.escape(mediaType) + "/schema");
+                    schema.putContent(mediaType, jsonSchema);
+                }
+            }
+        }
+        return schema;
+    }
+
+    private static boolean isJsonMediaType(String mediaType)
+    {
+        mediaType = StringUtil.asciiToLowerCase(mediaType);
+        return RequestSchema.isJson(mediaType) || "*/*".equals(mediaType) || "application/*".equals(mediaType);
+    }
+
+    private static List<RequestSchema.Parameter> parameters(JsonSchema.Compiler compiler, Object node, String location)
+    {
+        if (node == null)
+            return List.of();
+        if (!(node instanceof Collection || node instanceof Object[]))
+            throw new IllegalArgumentException("Expected array at " + location);
+        List<RequestSchema.Parameter> result = new ArrayList<>();
+        int index = 0;
+        for (Object item : JsonSchema.asList(node))
+        {
+            String itemLocation = location + "/" + index++;
+            Map<?, ?> parameter = dereference(compiler, item, itemLocation);
+            Object name = parameter.get("name");
+            Object in = parameter.get("in");
+            if (name == null || in == null)
+                throw new IllegalArgumentException("Invalid parameter at " + itemLocation);
+            RequestSchema.Location parameterLocation;
+            try
+            {
+                parameterLocation = RequestSchema.Location.from(in.toString());
+            }
+            catch (IllegalArgumentException x)
+            {
+                throw new IllegalArgumentException("Invalid parameter location " + in + " at " + itemLocation, x);
+            }
+            boolean required = Boolean.TRUE.equals(parameter.get("required"));
+            Object schemaNode = parameter.get("schema");
+            JsonSchema schema = schemaNode == null ? null : compiler.compile(schemaNode, itemLocation + "/schema");
+            Object explode = parameter.get("explode");
+            if (explode instanceof Boolean)
+                result.add(new RequestSchema.Parameter(name.toString(), parameterLocation, required, schema, (Boolean)explode));
+            else
+                result.add(new RequestSchema.Parameter(name.toString(), parameterLocation, required, schema));
+        }
+        return result;
+    }
+
+    private static Map<?, ?> dereference(JsonSchema.Compiler compiler, Object node, String location)
+    {
+        Map<?, ?> map = asMap(node, location);
+        Object ref = map.get("$ref");
+        if (ref == null)
+            return map;
+        return asMap(compiler.lookup(ref.toString(), location), ref.toString());
+    }
+
+    private static Map<?, ?> asMap(Object value, String location)
+    {
+        if (value instanceof Map)
+            return (Map<?, ?>)value;
+        throw new IllegalArgumentException("Expected object at " + location);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[version=%s,operations=%d]", getClass().getSimpleName(), hashCode(), _version, getOperationCount());
+    }
+}
@@ -0,0 +1,413 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.math.BigDecimal;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import javax.servlet.http.Cookie;
+import javax.servlet.http.HttpServletRequest;
+
+import org.eclipse.jetty.http.pathmap.PathSpec;
+import org.eclipse.jetty.http.pathmap.UriTemplatePathSpec;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.util.MultiMap;
+import org.eclipse.jetty.util.StringUtil;
+import org.eclipse.jetty.util.UrlEncoded;
+
+/**
+ * <p>The schema of a request, describing its parameters and its content,
+ * similarly to an OpenAPI operation.</p>
+ * <p>The request content is described by media type: content with a JSON
+ * media type, such as {@code application/json} or {@code application/*+json},
+ * is validated against the JSON Schema associated to the media type, while
+ * content with other media types is not validated.</p>
+ *
+ * @see Resolver
+ */
+public class RequestSchema
+{
+    private final List<Parameter> _parameters = new ArrayList<>();
+    private final Map<String, JsonSchema> _content = new LinkedHashMap<>();
+    private final String _name;
+    private PathSpec _pathSpec;
+    private boolean _bodyRequired;
+
+    /**
+     * @param name the name of this schema, for example the OpenAPI operation id
+     */
+    public RequestSchema(String name)
+    {
+        _name = Objects.requireNonNull(name);
+    }
+
+    public String getName()
+    {
+        return _name;
+    }
+
+    /**
+     * @return the path spec of the request, used to extract the path parameters
+     */
+    public PathSpec getPathSpec()
+    {
+        return _pathSpec;
+    }
+
+    /**
+     * @param pathSpec the path spec of the request; path parameters are
+     * only extracted from {@link UriTemplatePathSpec}s
+     */
+    public void setPathSpec(PathSpec pathSpec)
+    {
+        _pathSpec = pathSpec;
+    }
+
+    public boolean isBodyRequired()
+    {
+        return _bodyRequired;
+    }
+
+    public void setBodyRequired(boolean bodyRequired)
+    {
+        _bodyRequired = bodyRequired;
+    }
+
+    public List<Parameter> getParameters()
+    {
+        return _parameters;
+    }
+
+    public void addParameter(Parameter parameter)
+    {
+        _parameters.add(Objects.requireNonNull(parameter));
+    }
+
+    /**
+     * @return the supported media types, mapped to the JSON Schema of the content
+     */
+    public Map<String, JsonSchema> getContent()
+    {
+        return _content;
+    }
+
+    /**
+     * @param mediaType the media type, possibly with wildcards such as {@code application/*}
+     * @param schema the JSON Schema of the content, or null if the content is not validated
+     */
+    public void putContent(String mediaType, JsonSchema schema)
+    {
+        _content.put(StringUtil.asciiToLowerCase(mediaType), schema);
+    }
+
+    /**
+     * @param mimeType the request mime type, without parameters
+     * @return whether a content with the given mime type is allowed
+     */
+    public boolean isMediaTypeSupported(String mimeType)
+    {
+        return _content.isEmpty() || findMediaType(mimeType) != null;
+    }
+
+    /**
+     * @param mimeType the request mime type, without parameters
+     * @return the JSON Schema of the content with the given mime type, or null if the content must not be validated
+     */
+    public JsonSchema getBodySchema(String mimeType)
+    {
+        if (!isJson(mimeType))
+            return null;
+        String mediaType = findMediaType(mimeType);
+        return mediaType == null ? null : _content.get(mediaType);
+    }
+
+    private String findMediaType(String mimeType)
+    {
+        if (mimeType == null)
+            return null;
+        mimeType = StringUtil.asciiToLowerCase(mimeType);
+        if (_content.containsKey(mimeType))
+            return mimeType;
+        int slash = mimeType.indexOf('/');
+        if (slash > 0)
+        {
+            String wildcard = mimeType.substring(0, slash) + "/*";
+            if (_content.containsKey(wildcard))
+                return wildcard;
+        }
+        return _content.containsKey("*/*") ? "*/*" : null;
+    }
+
+    /**
+     * @param mimeType the mime type, without parameters
+     * @return whether the mime type is a JSON mime type
+     */
+    public static boolean isJson(String mimeType)
+    {
+        if (mimeType == null)
+            return false;
+        mimeType = StringUtil.asciiToLowerCase(mimeType);
+        return "application/json".equals(mimeType) || mimeType.endsWith("+json");
+    }
+
+    /**
+     * <p>Validates the path, query, header and cookie parameters of the given request.</p>
+     *
+     * @param request the request
+     * @return the list of violations, empty if the parameters are valid
+     */
+    public List<Violation> validateParameters(HttpServletRequest request)
+    {
+        if (_parameters.isEmpty())
+            return List.of();
+
+        Map<String, String> pathParams = Map.of();
+        if (_pathSpec instanceof UriTemplatePathSpec)
+        {
+            String path = Request.getBaseRequest(request).getPathInContext();
+            Map<String, String> params = path == null ? null : ((UriTemplatePathSpec)_pathSpec).getPathParams(path);
+            if (params != null)
+                pathParams = params;
+        }
+
+        MultiMap<String> queryParams = null;
+        List<Violation> violations = new ArrayList<>();
+        for (Parameter parameter : _parameters)
+        {
+            List<String> values;
+            switch (parameter.getLocation())
+            {
+                case PATH:
+                {
+                    String value = pathParams.get(parameter.getName());
+                    values = value == null ? List.of() : List.of(value);
+                    break;
+                }
+                case QUERY:
+                {
+                    if (queryParams == null)
+                    {
+                        // Do not use the request parameters, that may read the content.
+                        queryParams = new MultiMap<>();
+                        String query = request.getQueryString();
+                        if (query != null)
+                            UrlEncoded.decodeUtf8To(query, queryParams);
+                    }
+                    List<String> list = queryParams.getValues(parameter.getName());
+                    values = list == null ? List.of() : list;
+                    break;
+                }
+                case HEADER:
+                {
+                    values = Collections.list(request.getHeaders(parameter.getName()));
+                    break;
+                }
+                case COOKIE:
+                {
+                    values = new ArrayList<>();
+                    Cookie[] cookies = request.getCookies();
+                    if (cookies != null)
+                    {
+                        for (Cookie cookie : cookies)
+                        {
+                            if (cookie.getName().equals(parameter.getName()))
+                                values.add(cookie.getValue());
+                        }
+                    }
+                    break;
+                }
+                default:
+                {
+                    throw new IllegalStateException();
+                }
+            }
+
+            if (values.isEmpty())
+            {
+                if (parameter.isRequired())
+                    violations.add(new Violation("", parameter.toString(), "missing required parameter"));
+                continue;
+            }
+
+            JsonSchema schema = parameter.getSchema();
+            if (schema != null)
+                violations.addAll(schema.validate(parameter.coerce(values), parameter.toString()));
+        }
+        return violations;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,parameters=%d,content=%s]", getClass().getSimpleName(), hashCode(), _name, _parameters.size(), _content.keySet());
+    }
+
+    /**
+     * <p>Resolves the {@link RequestSchema} of a request.</p>
+     *
+     * @see OpenApi
+     */
+    @FunctionalInterface
+    public interface Resolver
+    {
+        /**
+         * @param request the request
+         * @return the schema of the request, or null if the request must not be validated
+         */
+        RequestSchema resolve(HttpServletRequest request);
+    }
+
+    /**
+     * The location of a request parameter.
+     */
+    public enum Location
+    {
+        PATH, QUERY, HEADER, COOKIE;
+
+        /**
+         * @param location the OpenAPI parameter location, such as {@code query}
+         * @return the corresponding location
+         */
+        public static Location from(String location)
+        {
+            return valueOf(StringUtil.asciiToUpperCase(location));
+        }
+    }
+
+    /**
+     * <p>A request parameter.</p>
+     * <p>Parameter values are strings, that are converted to the type declared
+     * by the parameter schema before validation, for example a query parameter
+     * with an {@code integer} schema is converted to a number.</p>
+     * <p>Array parameters are obtained either from multiple values, as in
+     * {@code ?id=1&id=2}, or from a single comma separated value, as in
+     * {@code ?id=1,2}, if the parameter is not {@code explode}d.</p>
+     */
+    public static class Parameter
+    {
+        private final String _name;
+        private final Location _location;
+        private final boolean _required;
+        private final JsonSchema _schema;
+        private final boolean _explode;
+
+        public Parameter(String name, Location location, boolean required, JsonSchema schema)
+        {
+            this(name, location, required, schema, location == Location.QUERY || location == Location.COOKIE);
+        }
+
+        public Parameter(String name, Location location, boolean required, JsonSchema schema, boolean explode)
+        {
+            _name = Objects.requireNonNull(name);
+            _location = Objects.requireNonNull(location);
+            // SPEC: path parameters are always required.
+            _required = required || location == Location.PATH;
+            _schema = schema;
+            _explode = explode;
+        }
+
+        public String getName()
+        {
+            return _name;
+        }
+
+        public Location getLocation()
+        {
+            return _location;
+        }
+
+        public boolean isRequired()
+        {
+            return _required;
+        }
+
+        public JsonSchema getSchema()
+        {
+            return _schema;
+        }
+
+        public boolean isExplode()
+        {
+            return _explode;
+        }
+
+        Object coerce(List<String> values)
+        {
+            Set<String> types = _schema.getTypes();
+            if (types.contains("array"))
+            {
+                List<String> items = values;
+                if (!_explode || values.size() == 1)
+                {
+                    items = new ArrayList<>();
+                    for (String value : values)
+                    {
+                        for (String item : StringUtil.csvSplit(value))
+                        {
+                            items.add(item);
+                        }
+                    }
+                }
+                JsonSchema itemSchema = _schema.getItems();
+                Set<String> itemTypes = itemSchema == null ? Set.of() : itemSchema.getTypes();
+                List<Object> result = new ArrayList<>(items.size());
+                for (String item : items)
+                {
+                    result.add(coerce(item, itemTypes));
+                }
+                return result;
+            }
+            return coerce(values.get(0), types);
+        }
+
+        private static Object coerce(String value, Set<String> types)
+        {
+            if (types.isEmpty() || types.contains("string"))
+                return value;
+            if (types.contains("integer") || types.contains("number"))
+            {
+                try
+                {
+                    return new BigDecimal(value.trim());
+                }
+                catch (NumberFormatException x)
+                {
+                    // Not a number, let the validation report the violation.
+                }
+            }
+            if (types.contains("boolean"))
+            {
+                if ("true".equalsIgnoreCase(value))
+                    return Boolean.TRUE;
+                if ("false".equalsIgnoreCase(value))
+                    return Boolean.FALSE;
+            }
+            if (types.contains("null") && value.isEmpty())
+                return null;
+            return value;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s parameter \"%s\"", _location.name().toLowerCase(Locale.ENGLISH), _name);
+        }
+    }
+}
@@ -0,0 +1,372 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.concurrent.atomic.LongAdder;
+import javax.servlet.DispatcherType;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpField;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.BufferedRequestContentHandler;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.resource.Resource;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A handler that validates the parameters and the content of requests
+ * against an OpenAPI 3.x document or a JSON Schema, before invoking the
+ * wrapped handlers.</p>
+ * <p>The schema of a request is resolved by a {@link RequestSchema.Resolver},
+ * typically an {@link OpenApi} document configured via {@link #setOpenApiLocation(String)},
+ * or a single JSON Schema that applies to the JSON content of all requests,
+ * configured via {@link #setSchemaLocation(String)}.
+ * The documents are compiled when this handler is started, so that invalid
+ * documents are reported at startup rather than at the first request.</p>
+ * <p>The parameters of the request are validated before reading the content.
+ * The JSON content is validated incrementally while it is read, so that it
+ * is never parsed into a tree of objects, and invalid content is rejected as
+ * soon as a violation is detected, without reading the rest of the content.
+ * Valid content is buffered as in {@link BufferedRequestContentHandler}, so that
+ * the wrapped handlers can read it as if it was not read by this handler.</p>
+ * <p>Invalid requests are rejected with a {@code 400} response, and requests with
+ * an unsupported media type with a {@code 415} response; the content of these
+ * responses is an RFC 9457 {@code application/problem+json} document, whose
+ * {@code errors} member lists the JSON pointers and the parameters that failed
+ * the validation.</p>
+ */
+@ManagedObject("Validates requests against an OpenAPI document or a JSON Schema")
+public class ValidationHandler extends BufferedRequestContentHandler
+{
+    /**
+     * The media type of RFC 9457 problem details.
+     */
+    public static final String PROBLEM_JSON = "application/problem+json";
+    private static final String VALIDATOR_ATTRIBUTE = ValidationHandler.class.getName() + ".validator";
+    private static final Logger LOG = LoggerFactory.getLogger(ValidationHandler.class);
+
+    private final LongAdder _validated = new LongAdder();
+    private final LongAdder _rejected = new LongAdder();
+    private RequestSchema.Resolver _resolver;
+    private String _openApiLocation;
+    private String _schemaLocation;
+    private int _maxViolations = JsonValidator.DEFAULT_MAX_VIOLATIONS;
+
+    /**
+     * @return the resolver of the request schemas
+     */
+    public RequestSchema.Resolver getResolver()
+    {
+        return _resolver;
+    }
+
+    /**
+     * @param resolver the resolver of the request schemas
+     */
+    public void setResolver(RequestSchema.Resolver resolver)
+    {
+        _resolver = Objects.requireNonNull(resolver);
+    }
+
+    /**
+     * @param openApi the OpenAPI document to validate requests against
+     */
+    public void setOpenApi(OpenApi openApi)
+    {
+        setResolver(openApi);
+    }
+
+    /**
+     * <p>Validates the JSON content of all requests against the given schema.</p>
+     *
+     * @param schema the JSON Schema of the request content
+     */
+    public void setJsonSchema(JsonSchema schema)
+    {
+        RequestSchema requestSchema = new RequestSchema("schema");
+        requestSchema.putContent("*/*", Objects.requireNonNull(schema));
+        setResolver(request -> requestSchema);
+    }
+
+    /**
+     * @return the location of the OpenAPI document in JSON format
+     */
+    @ManagedAttribute("The location of the OpenAPI document")
+    public String getOpenApiLocation()
+    {
+        return _openApiLocation;
+    }
+
+    /**
+     * @param location the location of the OpenAPI document in JSON format,
+     * as a file path or a URI, compiled when this handler is started
+     */
+    public void setOpenApiLocation(String location)
+    {
+        _openApiLocation = location;
+    }
+
+    /**
+     * @return the location of the JSON Schema of the request content
+     */
+    @ManagedAttribute("The location of the JSON Schema")
+    public String getSchemaLocation()
+    {
+        return _schemaLocation;
+    }
+
+    /**
+     * @param location the location of the JSON Schema of the request content,
+     * as a file path or a URI, compiled when this handler is started
+     */
+    public void setSchemaLocation(String location)
+    {
+        _schemaLocation = location;
+    }
+
+    /**
+     * @return the max number of violations reported for a request
+     */
+    @ManagedAttribute("The max number of violations reported for a request")
+    public int getMaxViolations()
+    {
+        return _maxViolations;
+    }
+
+    /**
+     * @param maxViolations the max number of violations reported for a request
+     */
+    public void setMaxViolations(int maxViolations)
+    {
+        if (maxViolations <= 0)
+            throw new IllegalArgumentException("Invalid max violations " + maxViolations);
+        _maxViolations = maxViolations;
+    }
+
+    @ManagedAttribute("The number of validated requests")
+    public long getValidatedRequests()
+    {
+        return _validated.sum();
+    }
+
+    @ManagedAttribute("The number of rejected requests")
+    public long getRejectedRequests()
+    {
+        return _rejected.sum();
+    }
+
+    @ManagedOperation(value = "Resets the statistics", impact = "ACTION")
+    public void reset()
+    {
+        _validated.reset();
+        _rejected.reset();
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        if (_openApiLocation != null && _schemaLocation != null)
+            throw new IllegalStateException("Both OpenAPI document and JSON Schema configured");
+        if (_openApiLocation != null)
+            setOpenApi(OpenApi.from(load(_openApiLocation)));
+        else if (_schemaLocation != null)
+            setJsonSchema(JsonSchema.from(load(_schemaLocation)));
+        else if (_resolver == null)
+            throw new IllegalStateException("No OpenAPI document or JSON Schema configured");
+        if (LOG.isDebugEnabled())
+            LOG.debug("Validating requests with {} for {}", _resolver, this);
+        super.doStart();
+    }
+
+    private static String load(String location) throws IOException
+    {
+        try (Resource resource = Resource.newResource(location))
+        {
+            if (!resource.exists())
+                throw new IllegalArgumentException("No such document " + location);
+            try (InputStream input = resource.getInputStream())
+            {
+                return IO.toString(input, StandardCharsets.UTF_8);
+            }
+        }
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        if (baseRequest.getDispatcherType() == DispatcherType.REQUEST &&
+            getBufferedContent(baseRequest) == null &&
+            baseRequest.getAttribute(VALIDATOR_ATTRIBUTE) == null)
+        {
+            if (!validate(baseRequest, request, response))
+            {
+                baseRequest.setHandled(true);
+                return;
+            }
+        }
+        super.handle(target, baseRequest, request, response);
+    }
+
+    private boolean validate(Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+    {
+        RequestSchema schema = _resolver.resolve(request);
+        if (schema == null)
+            return true;
+
+        _validated.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Validating {} against {}", baseRequest, schema);
+
+        List<Violation> violations = schema.validateParameters(request);
+        if (!violations.isEmpty())
+            return reject(response, HttpStatus.BAD_REQUEST_400, "Invalid request parameters", violations);
+
+        if (!super.shouldBuffer(baseRequest))
+        {
+            if (schema.isBodyRequired())
+                return reject(response, HttpStatus.BAD_REQUEST_400, "Missing request content", List.of());
+            return true;
+        }
+
+        String contentType = request.getContentType();
+        String mimeType = contentType == null ? null : HttpField.valueParameters(contentType, null);
+        if (!schema.isMediaTypeSupported(mimeType))
+            return reject(response, HttpStatus.UNSUPPORTED_MEDIA_TYPE_415, "Unsupported media type " + mimeType, List.of());
+
+        JsonSchema bodySchema = schema.getBodySchema(mimeType);
+        if (bodySchema != null)
+        {
+            JsonValidator validator = new JsonValidator(bodySchema, _maxViolations);
+            baseRequest.setAttribute(VALIDATOR_ATTRIBUTE, validator);
+        }
+        return true;
+    }
+
+    @Override
+    protected boolean shouldBuffer(Request request)
+    {
+        // Only the content of requests that must be validated is buffered.
+        return request.getAttribute(VALIDATOR_ATTRIBUTE) != null && super.shouldBuffer(request);
+    }
+
+    @Override
+    protected void onContent(Request request, byte[] bytes, int offset, int length) throws Exception
+    {
+        JsonValidator validator = (JsonValidator)request.getAttribute(VALIDATOR_ATTRIBUTE);
+        if (validator == null)
+            return;
+        validator.parse(bytes, offset, length);
+        if (!validator.isValid())
+            throw new ValidationException(validator);
+    }
+
+    @Override
+    protected void onContentComplete(Request request) throws Exception
+    {
+        JsonValidator validator = (JsonValidator)request.getAttribute(VALIDATOR_ATTRIBUTE);
+        if (validator == null)
+            return;
+        validator.complete();
+        if (!validator.isValid())
+            throw new ValidationException(validator);
+        request.removeAttribute(VALIDATOR_ATTRIBUTE);
+    }
+
+    @Override
+    protected void onContentFailure(Request request, Throwable failure) throws IOException
+    {
+        if (failure instanceof ValidationException)
+        {
+            JsonValidator validator = ((ValidationException)failure)._validator;
+            String title = validator.isMalformed() ? "Malformed request content" : "Invalid request content";
+            reject(request.getResponse(), HttpStatus.BAD_REQUEST_400, title, validator.getViolations());
+        }
+        else
+        {
+            super.onContentFailure(request, failure);
+        }
+    }
+
+    private boolean reject(HttpServletResponse response, int status, String title, List<Violation> violations) throws IOException
+    {
+        _rejected.increment();
+        if (LOG.isDebugEnabled())
+            LOG.debug("Rejecting request with {} {}: {}", status, title, violations);
+        if (response.isCommitted())
+            return false;
+
+        Map<String, Object> problem = new LinkedHashMap<>();
+        problem.put("type", "about:blank");
+        problem.put("title", HttpStatus.getMessage(status));
+        problem.put("status", status);
+        problem.put("detail", title);
+        if (!violations.isEmpty())
+        {
+            List<Map<String, Object>> errors = new ArrayList<>(violations.size());
+            for (Violation violation : violations)
+            {
+                Map<String, Object> error = new LinkedHashMap<>();
+                if (violation.getParameter() != null)
+                    error.put("parameter", violation.getParameter());
+                else
+                    error.put("pointer", violation.getPointer());
+                error.put("detail", violation.getMessage());
+                errors.add(error);
+            }
+            problem.put("errors", errors);
+        }
+
+        byte[] content = new JSON().toJSON(problem).getBytes(StandardCharsets.UTF_8);
+        response.setStatus(status);
+        response.setContentType(PROBLEM_JSON);
+        response.setCharacterEncoding(StandardCharsets.UTF_8.name());
+        response.setContentLength(content.length);
+        response.getOutputStream().write(content);
+        response.flushBuffer();
+        return false;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[resolver=%s]", getClass().getSimpleName(), hashCode(), _resolver);
+    }
+
+    private static class ValidationException extends Exception
+    {
+        private final JsonValidator _validator;
+
+        private ValidationException(JsonValidator validator)
+        {
+            super("Invalid content", null, false, false);
+            _validator = validator;
+        }
+    }
+}
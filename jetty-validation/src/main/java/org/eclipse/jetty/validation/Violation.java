@@ -0,0 +1,83 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.util.Objects;
+
+/**
+ * <p>A validation violation, reporting where the violation
+ * occurred and a human readable description of the violation.</p>
+ * <p>The location is a JSON pointer within the request body
+ * when the violation is about the body, or the parameter name
+ * when the violation is about a request parameter.</p>
+ */
+public class Violation
+{
+    private final String _pointer;
+    private final String _parameter;
+    private final String _message;
+
+    /**
+     * @param pointer the JSON pointer of the invalid value within the document
+     * @param message the description of the violation
+     */
+    public Violation(String pointer, String message)
+    {
+        this(pointer, null, message);
+    }
+
+    /**
+     * @param pointer the JSON pointer of the invalid value within the parameter value
+     * @param parameter the description of the invalid parameter, such as {@code query "id"}, or null
+     * @param message the description of the violation
+     */
+    public Violation(String pointer, String parameter, String message)
+    {
+        _pointer = Objects.requireNonNull(pointer);
+        _parameter = parameter;
+        _message = Objects.requireNonNull(message);
+    }
+
+    /**
+     * @return the JSON pointer of the invalid value, empty for the whole document or parameter value
+     */
+    public String getPointer()
+    {
+        return _pointer;
+    }
+
+    /**
+     * @return the description of the invalid parameter, or null if the violation is about the body
+     */
+    public String getParameter()
+    {
+        return _parameter;
+    }
+
+    /**
+     * @return the description of the violation
+     */
+    public String getMessage()
+    {
+        return _message;
+    }
+
+    @Override
+    public String toString()
+    {
+        if (_parameter == null)
+            return String.format("%s: %s", _pointer.isEmpty() ? "/" : _pointer, _message);
+        return String.format("%s%s: %s", _parameter, _pointer, _message);
+    }
+}
@@ -0,0 +1,172 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import java.util.Map;
+
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.empty;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class JsonValidatorTest
+{
+    private static final String PERSON = "{" +
+        "\"type\":\"object\"," +
+        "\"required\":[\"name\",\"age\"]," +
+        "\"additionalProperties\":false," +
+        "\"properties\":{" +
+        "\"name\":{\"type\":\"string\",\"minLength\":1}," +
+        "\"age\":{\"type\":\"integer\",\"minimum\":0}," +
+        "\"tags\":{\"type\":\"array\",\"items\":{\"type\":\"string\"},\"maxItems\":2}," +
+        "\"email\":{\"type\":\"string\",\"pattern\":\"^[^@]+@[^@]+$\"}" +
+        "}}";
+
+    private JsonValidator validate(JsonSchema schema, String json, boolean byteByByte)
+    {
+        JsonValidator validator = schema.newValidator();
+        byte[] bytes = json.getBytes(StandardCharsets.UTF_8);
+        if (byteByByte)
+        {
+            for (int i = 0; i < bytes.length; ++i)
+            {
+                validator.parse(bytes, i, 1);
+            }
+        }
+        else
+        {
+            validator.parse(bytes, 0, bytes.length);
+        }
+        validator.complete();
+        return validator;
+    }
+
+    @Test
+    public void testValidDocument()
+    {
+        JsonSchema schema = JsonSchema.from(PERSON);
+        String json = "{\"name\":\"J\\u00fcrgen é\",\"age\":42,\"tags\":[\"a\",\"b\"],\"email\":\"j@example.com\"}";
+        for (boolean byteByByte : new boolean[]{false, true})
+        {
+            JsonValidator validator = validate(schema, json, byteByByte);
+            assertThat(validator.getViolations().toString(), validator.isValid(), is(true));
+        }
+    }
+
+    @Test
+    public void testViolationsHavePointers()
+    {
+        JsonSchema schema = JsonSchema.from(PERSON);
+        String json = "{\"name\":\"\",\"age\":-1.5,\"tags\":[\"a\",2,\"c\"],\"extra\":null}";
+        for (boolean byteByByte : new boolean[]{false, true})
+        {
+            JsonValidator validator = validate(schema, json, byteByByte);
+            assertThat(validator.isValid(), is(false));
+            assertThat(validator.isMalformed(), is(false));
+            String violations = validator.getViolations().toString();
+            assertThat(violations, containsString("/name"));
+            assertThat(violations, containsString("/age"));
+            assertThat(violations, containsString("/tags/1"));
+            assertThat(violations, containsString("at most 2 items"));
+            assertThat(violations, containsString("\"extra\""));
+        }
+    }
+
+    @Test
+    public void testMissingRequiredProperty()
+    {
+        JsonValidator validator = validate(JsonSchema.from(PERSON), "{\"name\":\"x\"}", false);
+        List<Violation> violations = validator.getViolations();
+        assertThat(violations, hasSize(1));
+        assertThat(violations.get(0).getPointer(), is(""));
+        assertThat(violations.get(0).getMessage(), containsString("\"age\""));
+    }
+
+    @Test
+    public void testCombinators()
+    {
+        JsonSchema schema = JsonSchema.from("{" +
+            "\"oneOf\":[{\"type\":\"integer\"},{\"type\":\"number\",\"minimum\":10}]," +
+            "\"not\":{\"const\":3}" +
+            "}");
+        assertThat(validate(schema, "1", false).isValid(), is(true));
+        assertThat(validate(schema, "3", false).isValid(), is(false));
+        // Both branches match.
+        assertThat(validate(schema, "12", false).isValid(), is(false));
+        assertThat(validate(schema, "10.5", false).isValid(), is(true));
+        assertThat(validate(schema, "\"x\"", false).isValid(), is(false));
+
+        JsonSchema anyOf = JsonSchema.from("{\"anyOf\":[{\"type\":\"string\"},{\"type\":\"array\",\"items\":{\"type\":\"boolean\"}}]}");
+        assertThat(validate(anyOf, "[true,false]", true).isValid(), is(true));
+        assertThat(validate(anyOf, "[true,1]", true).isValid(), is(false));
+    }
+
+    @Test
+    public void testRecursiveReference()
+    {
+        JsonSchema schema = JsonSchema.from("{" +
+            "\"$ref\":\"#/$defs/node\"," +
+            "\"$defs\":{\"node\":{\"type\":\"object\",\"required\":[\"value\"],\"properties\":{" +
+            "\"value\":{\"type\":\"integer\"}," +
+            "\"children\":{\"type\":\"array\",\"items\":{\"$ref\":\"#/$defs/node\"}}" +
+            "}}}}");
+        assertThat(validate(schema, "{\"value\":1,\"children\":[{\"value\":2,\"children\":[{\"value\":3}]}]}", true).isValid(), is(true));
+        JsonValidator validator = validate(schema, "{\"value\":1,\"children\":[{\"value\":2,\"children\":[{\"value\":\"3\"}]}]}", true);
+        assertThat(validator.getViolations(), hasSize(1));
+        assertThat(validator.getViolations().get(0).getPointer(), is("/children/0/children/0/value"));
+    }
+
+    @Test
+    public void testMalformedDocuments()
+    {
+        JsonSchema schema = JsonSchema.from("true");
+        for (String json : List.of("{", "{\"a\" 1}", "[1,]", "01", "\"abc", "{} {}", "tru", "[1 2]", ""))
+        {
+            JsonValidator validator = validate(schema, json, false);
+            assertThat(json, validator.isMalformed(), is(true));
+            assertThat(json, validator.isValid(), is(false));
+        }
+    }
+
+    @Test
+    public void testMaxDepth()
+    {
+        JsonValidator validator = JsonSchema.from("true").newValidator();
+        validator.setMaxDepth(4);
+        byte[] bytes = "[[[[[1]]]]]".getBytes(StandardCharsets.UTF_8);
+        validator.parse(bytes, 0, bytes.length);
+        assertThat(validator.isMalformed(), is(true));
+    }
+
+    @Test
+    public void testValidateValue()
+    {
+        JsonSchema schema = JsonSchema.from(PERSON);
+        assertThat(schema.validate(Map.of("name", "x", "age", 1)), empty());
+        assertThat(schema.validate(Map.of("name", "x", "age", "1")), hasSize(1));
+    }
+
+    @Test
+    public void testInvalidSchema()
+    {
+        assertThrows(IllegalArgumentException.class, () -> JsonSchema.from("{\"type\":\"object\",\"properties\":[]}"));
+        assertThrows(IllegalArgumentException.class, () -> JsonSchema.from("{\"$ref\":\"other.json#/foo\"}"));
+    }
+}
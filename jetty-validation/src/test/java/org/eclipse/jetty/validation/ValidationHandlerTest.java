@@ -0,0 +1,303 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.validation;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.Map;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.ajax.JSON;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+@ExtendWith(WorkDirExtension.class)
+public class ValidationHandlerTest
+{
+    private static final String OPENAPI = "{" +
+        "\"openapi\":\"3.1.0\"," +
+        "\"info\":{\"title\":\"Pets\",\"version\":\"1\"}," +
+        "\"paths\":{" +
+        "  \"/pets\":{" +
+        "    \"get\":{\"parameters\":[{\"name\":\"limit\",\"in\":\"query\",\"schema\":{\"type\":\"integer\",\"maximum\":100}}]}," +
+        "    \"post\":{\"operationId\":\"createPet\",\"requestBody\":{\"required\":true,\"content\":{" +
+        "      \"application/json\":{\"schema\":{\"$ref\":\"#/components/schemas/Pet\"}}" +
+        "    }}}" +
+        "  }," +
+        "  \"/pets/{id}\":{" +
+        "    \"parameters\":[{\"$ref\":\"#/components/parameters/id\"}]," +
+        "    \"get\":{\"parameters\":[{\"name\":\"X-Trace\",\"in\":\"header\",\"required\":true,\"schema\":{\"type\":\"string\"}}]}" +
+        "  }" +
+        "}," +
+        "\"components\":{" +
+        "  \"parameters\":{\"id\":{\"name\":\"id\",\"in\":\"path\",\"required\":true,\"schema\":{\"type\":\"integer\",\"minimum\":1}}}," +
+        "  \"schemas\":{\"Pet\":{\"type\":\"object\",\"required\":[\"name\"],\"properties\":{" +
+        "    \"name\":{\"type\":\"string\"}," +
+        "    \"age\":{\"type\":\"integer\",\"minimum\":0}" +
+        "  }}}" +
+        "}}";
+
+    public WorkDir workDir;
+    private final AtomicInteger handled = new AtomicInteger();
+    private Server server;
+    private LocalConnector connector;
+    private ValidationHandler validationHandler;
+
+    @BeforeEach
+    public void prepare()
+    {
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+        validationHandler = new ValidationHandler();
+        validationHandler.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                baseRequest.setHandled(true);
+                handled.incrementAndGet();
+                response.setContentType("application/json");
+                IO.copy(request.getInputStream(), response.getOutputStream());
+            }
+        });
+        server.setHandler(validationHandler);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private void start(String openApi) throws Exception
+    {
+        validationHandler.setOpenApi(OpenApi.from(openApi));
+        server.start();
+    }
+
+    private HttpTester.Response get(String path, String... headers) throws Exception
+    {
+        StringBuilder request = new StringBuilder("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n");
+        for (String header : headers)
+        {
+            request.append(header).append("\r\n");
+        }
+        request.append("Connection: close\r\n\r\n");
+        return HttpTester.parseResponse(connector.getResponse(request.toString()));
+    }
+
+    private HttpTester.Response post(String path, String contentType, String content) throws Exception
+    {
+        byte[] bytes = content.getBytes(StandardCharsets.UTF_8);
+        String request = "POST " + path + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Type: " + contentType + "\r\n" +
+            "Content-Length: " + bytes.length + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            content;
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> parseProblem(HttpTester.Response response)
+    {
+        assertThat(response.get(HttpHeader.CONTENT_TYPE), containsString(ValidationHandler.PROBLEM_JSON));
+        Map<String, Object> problem = (Map<String, Object>)new JSON().fromJSON(response.getContent());
+        assertThat(((Number)problem.get("status")).intValue(), is(response.getStatus()));
+        return problem;
+    }
+
+    @SuppressWarnings("unchecked")
+    private static Map<String, Object> firstError(Map<String, Object> problem)
+    {
+        Object[] errors = (Object[])problem.get("errors");
+        assertThat(errors, notNullValue());
+        return (Map<String, Object>)errors[0];
+    }
+
+    @Test
+    public void testValidContentIsHandled() throws Exception
+    {
+        start(OPENAPI);
+
+        String content = "{\"name\":\"Rex\",\"age\":3}";
+        HttpTester.Response response = post("/pets", "application/json; charset=utf-8", content);
+
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is(content));
+        assertThat(handled.get(), is(1));
+        assertThat(validationHandler.getValidatedRequests(), is(1L));
+        assertThat(validationHandler.getRejectedRequests(), is(0L));
+    }
+
+    @Test
+    public void testInvalidContentIsRejected() throws Exception
+    {
+        start(OPENAPI);
+
+        HttpTester.Response response = post("/pets", "application/json", "{\"name\":\"Rex\",\"age\":-3}");
+
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        Map<String, Object> error = firstError(parseProblem(response));
+        assertThat(error.get("pointer"), is("/age"));
+        assertThat(handled.get(), is(0));
+        assertThat(validationHandler.getRejectedRequests(), is(1L));
+    }
+
+    @Test
+    public void testMalformedContentIsRejected() throws Exception
+    {
+        start(OPENAPI);
+
+        HttpTester.Response response = post("/pets", "application/json", "{\"name\":\"Rex\",");
+
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(parseProblem(response).get("detail"), is("Malformed request content"));
+        assertThat(handled.get(), is(0));
+    }
+
+    @Test
+    public void testInvalidContentIsRejectedBeforeReadingAllContent() throws Exception
+    {
+        start(OPENAPI);
+
+        // The last chunk is never sent.
+        String request = "POST /pets HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            "Content-Type: application/json\r\n" +
+            "Transfer-Encoding: chunked\r\n" +
+            "\r\n" +
+            "8\r\n" +
+            "{\"name\":\r\n" +
+            "2\r\n" +
+            "1,\r\n";
+        LocalConnector.LocalEndPoint endPoint = connector.executeRequest(request);
+        HttpTester.Response response = HttpTester.parseResponse(endPoint.getResponse(false, 5, TimeUnit.SECONDS));
+
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(response.get(HttpHeader.CONNECTION), is("close"));
+        assertThat(firstError(parseProblem(response)).get("pointer"), is("/name"));
+        assertThat(handled.get(), is(0));
+    }
+
+    @Test
+    public void testUnsupportedMediaType() throws Exception
+    {
+        start(OPENAPI);
+
+        HttpTester.Response response = post("/pets", "text/plain", "Rex");
+
+        assertThat(response.getStatus(), is(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415));
+        parseProblem(response);
+        assertThat(handled.get(), is(0));
+    }
+
+    @Test
+    public void testMissingRequiredContent() throws Exception
+    {
+        start(OPENAPI);
+
+        HttpTester.Response response = post("/pets", "application/json", "");
+
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(parseProblem(response).get("detail"), is("Missing request content"));
+    }
+
+    @Test
+    public void testParameters() throws Exception
+    {
+        start(OPENAPI);
+
+        assertThat(get("/pets?limit=10").getStatus(), is(HttpStatus.OK_200));
+        assertThat(get("/pets/7", "X-Trace: abc").getStatus(), is(HttpStatus.OK_200));
+
+        HttpTester.Response response = get("/pets?limit=1000");
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(firstError(parseProblem(response)).get("parameter"), is("query parameter \"limit\""));
+
+        response = get("/pets/0", "X-Trace: abc");
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(firstError(parseProblem(response)).get("parameter"), is("path parameter \"id\""));
+
+        response = get("/pets/abc", "X-Trace: abc");
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+
+        response = get("/pets/7");
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        assertThat(firstError(parseProblem(response)).get("detail"), is("missing required parameter"));
+
+        assertThat(handled.get(), is(2));
+    }
+
+    @Test
+    public void testUnknownOperationIsNotValidated() throws Exception
+    {
+        start(OPENAPI);
+
+        assertThat(get("/other").getStatus(), is(HttpStatus.OK_200));
+        assertThat(post("/other", "application/json", "{").getStatus(), is(HttpStatus.OK_200));
+        assertThat(validationHandler.getValidatedRequests(), is(0L));
+        assertThat(handled.get(), is(2));
+    }
+
+    @Test
+    public void testSchemaLocation() throws Exception
+    {
+        Path schema = workDir.getEmptyPathDir().resolve("schema.json");
+        Files.writeString(schema, "{\"type\":\"array\",\"items\":{\"type\":\"integer\"}}");
+        validationHandler.setSchemaLocation(schema.toString());
+        server.start();
+
+        assertThat(post("/any", "application/json", "[1,2,3]").getStatus(), is(HttpStatus.OK_200));
+        assertThat(post("/any", "application/vnd.api+json", "[1,\"2\"]").getStatus(), is(HttpStatus.BAD_REQUEST_400));
+        // Non JSON content is not validated.
+        assertThat(post("/any", "text/plain", "[1,\"2\"]").getStatus(), is(HttpStatus.OK_200));
+    }
+
+    @Test
+    public void testInvalidDocumentFailsStartup()
+    {
+        assertThrows(IllegalArgumentException.class, () -> OpenApi.from("{\"openapi\":\"2.0\"}"));
+        assertThrows(IllegalArgumentException.class, () -> OpenApi.from(OPENAPI.replace("#/components/schemas/Pet", "#/components/schemas/Dog")));
+
+        validationHandler.setOpenApiLocation(workDir.getEmptyPathDir().resolve("missing.json").toString());
+        assertThrows(Exception.class, () -> server.start());
+        assertThat(validationHandler.isStarted(), is(false));
+    }
+}
@@ -0,0 +1,21 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Makes the WebDAV servlet, org.eclipse.jetty.webdav.WebDavServlet,
+available to web applications, to serve and modify their resources
+with WebDAV clients.
+The servlet must be declared in the web application descriptor,
+along with the security constraints that restrict access to it.
+
+[tags]
+server
+webapp
+
+[depend]
+servlet
+
+[lib]
+lib/jetty-webdav-${jetty.version}.jar
+
+[ini]
+jetty.webapp.addServerClasses+=,-org.eclipse.jetty.webdav.
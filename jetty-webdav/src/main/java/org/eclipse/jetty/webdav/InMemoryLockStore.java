@@ -0,0 +1,106 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+
+/**
+ * <p>A {@link LockStore} that keeps the locks in memory,
+ * so that they are lost when the server restarts.</p>
+ */
+@ManagedObject("In memory WebDAV lock store")
+public class InMemoryLockStore implements LockStore
+{
+    private final Map<String, WebDavLock> locks = new HashMap<>();
+
+    @Override
+    public synchronized boolean lock(WebDavLock lock)
+    {
+        purge();
+        for (WebDavLock existing : locks.values())
+        {
+            boolean overlap = existing.covers(lock.getPath()) || lock.covers(existing.getPath());
+            if (overlap && (existing.isExclusive() || lock.isExclusive()))
+                return false;
+        }
+        locks.put(lock.getToken(), lock);
+        return true;
+    }
+
+    @Override
+    public synchronized WebDavLock refresh(String token, long timeout)
+    {
+        purge();
+        WebDavLock lock = locks.get(token);
+        if (lock == null)
+            return null;
+        lock = lock.refresh(timeout);
+        locks.put(token, lock);
+        return lock;
+    }
+
+    @Override
+    public synchronized WebDavLock unlock(String token)
+    {
+        purge();
+        return locks.remove(token);
+    }
+
+    @Override
+    public synchronized List<WebDavLock> getLocks(String path, boolean descendants)
+    {
+        purge();
+        List<WebDavLock> result = new ArrayList<>();
+        for (WebDavLock lock : locks.values())
+        {
+            if (lock.covers(path) || descendants && WebDav.isDescendant(lock.getPath(), path))
+                result.add(lock);
+        }
+        return result;
+    }
+
+    @Override
+    public synchronized void removeLocks(String path)
+    {
+        locks.values().removeIf(lock -> WebDav.isSelfOrDescendant(lock.getPath(), path));
+    }
+
+    /**
+     * @return the number of locks
+     */
+    @ManagedAttribute("The number of locks")
+    public synchronized int getSize()
+    {
+        purge();
+        return locks.size();
+    }
+
+    private void purge()
+    {
+        long now = System.nanoTime();
+        locks.values().removeIf(lock -> lock.isExpired(now));
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[size=%d]", getClass().getSimpleName(), hashCode(), getSize());
+    }
+}
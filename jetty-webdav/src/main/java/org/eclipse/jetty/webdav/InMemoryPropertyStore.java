@@ -0,0 +1,95 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.util.Collection;
+import java.util.HashMap;
+import java.util.Map;
+import javax.xml.namespace.QName;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+
+/**
+ * <p>A {@link PropertyStore} that keeps the dead properties in memory,
+ * so that they are lost when the server restarts.</p>
+ */
+@ManagedObject("In memory WebDAV property store")
+public class InMemoryPropertyStore implements PropertyStore
+{
+    private final Map<String, Map<QName, String>> properties = new HashMap<>();
+
+    @Override
+    public synchronized Map<QName, String> getProperties(String path)
+    {
+        Map<QName, String> result = properties.get(path);
+        return result == null ? Map.of() : Map.copyOf(result);
+    }
+
+    @Override
+    public synchronized void update(String path, Map<QName, String> set, Collection<QName> remove)
+    {
+        Map<QName, String> current = properties.computeIfAbsent(path, p -> new HashMap<>());
+        current.keySet().removeAll(remove);
+        current.putAll(set);
+        if (current.isEmpty())
+            properties.remove(path);
+    }
+
+    @Override
+    public synchronized void copy(String source, String destination)
+    {
+        deleteTree(destination);
+        Map<String, Map<QName, String>> copies = new HashMap<>();
+        properties.forEach((path, values) ->
+        {
+            if (WebDav.isSelfOrDescendant(path, source))
+                copies.put(WebDav.rebase(path, source, destination), new HashMap<>(values));
+        });
+        properties.putAll(copies);
+    }
+
+    @Override
+    public synchronized void move(String source, String destination)
+    {
+        copy(source, destination);
+        deleteTree(source);
+    }
+
+    @Override
+    public synchronized void delete(String path)
+    {
+        deleteTree(path);
+    }
+
+    private void deleteTree(String path)
+    {
+        properties.keySet().removeIf(p -> WebDav.isSelfOrDescendant(p, path));
+    }
+
+    /**
+     * @return the number of resources that have dead properties
+     */
+    @ManagedAttribute("The number of resources with properties")
+    public synchronized int getSize()
+    {
+        return properties.size();
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[size=%d]", getClass().getSimpleName(), hashCode(), getSize());
+    }
+}
@@ -0,0 +1,65 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.util.List;
+
+/**
+ * <p>A store of the {@link WebDavLock}s of WebDAV resources.</p>
+ * <p>Paths are decoded paths in context that start with {@code /} and do not
+ * end with {@code /}, except the root path.
+ * Implementations must be thread-safe, and must not return expired locks.</p>
+ *
+ * @see InMemoryLockStore
+ */
+public interface LockStore
+{
+    /**
+     * <p>Adds the given lock, unless it conflicts with an existing lock.</p>
+     * <p>A lock conflicts with an existing lock that covers the same resources
+     * when either lock is exclusive.</p>
+     *
+     * @param lock the lock to add
+     * @return true if the lock was added, false if it conflicts with an existing lock
+     */
+    boolean lock(WebDavLock lock);
+
+    /**
+     * @param token the lock token
+     * @param timeout the new timeout in seconds
+     * @return the refreshed lock, or null if there is no lock with the given token
+     */
+    WebDavLock refresh(String token, long timeout);
+
+    /**
+     * @param token the lock token
+     * @return the removed lock, or null if there is no lock with the given token
+     */
+    WebDavLock unlock(String token);
+
+    /**
+     * @param path the resource path
+     * @param descendants whether to also return the locks of the descendants of the resource
+     * @return the locks that cover the resource with the given path
+     */
+    List<WebDavLock> getLocks(String path, boolean descendants);
+
+    /**
+     * <p>Removes the locks of a resource and of its descendants,
+     * typically because the resource was deleted or moved.</p>
+     *
+     * @param path the resource path
+     */
+    void removeLocks(String path);
+}
@@ -0,0 +1,102 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.util.List;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpStatus;
+
+/**
+ * <p>Builds and sends a {@code 207 Multi-Status} response.</p>
+ */
+class MultiStatus
+{
+    private final StringBuilder xml = new StringBuilder();
+
+    MultiStatus()
+    {
+        xml.append(WebDav.XML_DECLARATION).append("<D:multistatus xmlns:D=\"DAV:\">");
+    }
+
+    /**
+     * <p>Adds a response with a single status for a resource.</p>
+     *
+     * @param href the encoded href of the resource
+     * @param status the status of the resource
+     */
+    void response(String href, int status)
+    {
+        xml.append("<D:response><D:href>");
+        WebDav.escape(xml, href);
+        xml.append("</D:href>");
+        status(status);
+        xml.append("</D:response>");
+    }
+
+    /**
+     * <p>Starts a response with properties for a resource,
+     * followed by {@link #propstat(int, List)} calls and ended by {@link #endResponse()}.</p>
+     *
+     * @param href the encoded href of the resource
+     */
+    void startResponse(String href)
+    {
+        xml.append("<D:response><D:href>");
+        WebDav.escape(xml, href);
+        xml.append("</D:href>");
+    }
+
+    /**
+     * @param status the status of the properties
+     * @param properties the XML of the properties
+     */
+    void propstat(int status, List<String> properties)
+    {
+        if (properties.isEmpty())
+            return;
+        xml.append("<D:propstat><D:prop>");
+        properties.forEach(xml::append);
+        xml.append("</D:prop>");
+        status(status);
+        xml.append("</D:propstat>");
+    }
+
+    void endResponse()
+    {
+        xml.append("</D:response>");
+    }
+
+    private void status(int status)
+    {
+        xml.append("<D:status>HTTP/1.1 ").append(status).append(' ').append(HttpStatus.getMessage(status)).append("</D:status>");
+    }
+
+    void send(HttpServletResponse response) throws IOException
+    {
+        xml.append("</D:multistatus>");
+        send(response, HttpStatus.MULTI_STATUS_207, xml.toString());
+    }
+
+    static void send(HttpServletResponse response, int status, String xml) throws IOException
+    {
+        byte[] content = xml.getBytes(StandardCharsets.UTF_8);
+        response.setStatus(status);
+        response.setContentType("application/xml; charset=utf-8");
+        response.setContentLength(content.length);
+        response.getOutputStream().write(content);
+    }
+}
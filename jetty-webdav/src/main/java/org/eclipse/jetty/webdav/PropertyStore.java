@@ -0,0 +1,72 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.util.Collection;
+import java.util.Map;
+import javax.xml.namespace.QName;
+
+/**
+ * <p>A store of the dead properties of WebDAV resources, that is the properties
+ * set by clients with {@code PROPPATCH}, as opposed to the live properties
+ * computed from the resources themselves.</p>
+ * <p>Property values are stored as the text content of the property element.</p>
+ * <p>Paths are decoded paths in context that start with {@code /} and do not
+ * end with {@code /}, except the root path.
+ * Implementations must be thread-safe.</p>
+ *
+ * @see InMemoryPropertyStore
+ */
+public interface PropertyStore
+{
+    /**
+     * @param path the resource path
+     * @return the dead properties of the resource, never null
+     */
+    Map<QName, String> getProperties(String path);
+
+    /**
+     * <p>Atomically sets and removes dead properties of a resource.</p>
+     *
+     * @param path the resource path
+     * @param set the properties to set
+     * @param remove the names of the properties to remove
+     */
+    void update(String path, Map<QName, String> set, Collection<QName> remove);
+
+    /**
+     * <p>Copies the properties of a resource and of its descendants,
+     * replacing the properties of the destination and of its descendants.</p>
+     *
+     * @param source the source resource path
+     * @param destination the destination resource path
+     */
+    void copy(String source, String destination);
+
+    /**
+     * <p>Moves the properties of a resource and of its descendants,
+     * replacing the properties of the destination and of its descendants.</p>
+     *
+     * @param source the source resource path
+     * @param destination the destination resource path
+     */
+    void move(String source, String destination);
+
+    /**
+     * <p>Deletes the properties of a resource and of its descendants.</p>
+     *
+     * @param path the resource path
+     */
+    void delete(String path);
+}
@@ -0,0 +1,262 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.io.ByteArrayInputStream;
+import java.io.IOException;
+import java.util.ArrayList;
+import java.util.List;
+import javax.xml.XMLConstants;
+import javax.xml.namespace.QName;
+import javax.xml.parsers.DocumentBuilder;
+import javax.xml.parsers.DocumentBuilderFactory;
+import javax.xml.parsers.ParserConfigurationException;
+
+import org.w3c.dom.Document;
+import org.w3c.dom.Element;
+import org.w3c.dom.Node;
+import org.xml.sax.SAXException;
+
+/**
+ * <p>Utility methods for the WebDAV paths and XML bodies.</p>
+ * <p>Paths are paths in context, decoded, that start with {@code /}
+ * and never end with {@code /}, except the root path.</p>
+ */
+final class WebDav
+{
+    static final String NAMESPACE = "DAV:";
+    static final String XML_DECLARATION = "<?xml version=\"1.0\" encoding=\"utf-8\"?>";
+
+    private WebDav()
+    {
+    }
+
+    /**
+     * @param path a path in context
+     * @return the path without the trailing {@code /}, or {@code /} for the root path
+     */
+    static String normalize(String path)
+    {
+        if (path == null || path.isEmpty())
+            return "/";
+        while (path.length() > 1 && path.endsWith("/"))
+        {
+            path = path.substring(0, path.length() - 1);
+        }
+        return path;
+    }
+
+    /**
+     * @param path a normalized path
+     * @return the parent path, or null for the root path
+     */
+    static String parent(String path)
+    {
+        if ("/".equals(path))
+            return null;
+        int slash = path.lastIndexOf('/');
+        return slash <= 0 ? "/" : path.substring(0, slash);
+    }
+
+    /**
+     * @param path a normalized path
+     * @param ancestor a normalized path
+     * @return whether the path is a strict descendant of the ancestor
+     */
+    static boolean isDescendant(String path, String ancestor)
+    {
+        if (path.equals(ancestor))
+            return false;
+        if ("/".equals(ancestor))
+            return true;
+        return path.startsWith(ancestor) && path.charAt(ancestor.length()) == '/';
+    }
+
+    /**
+     * @param path a normalized path
+     * @param ancestor a normalized path
+     * @return whether the path is the given ancestor or one of its descendants
+     */
+    static boolean isSelfOrDescendant(String path, String ancestor)
+    {
+        return path.equals(ancestor) || isDescendant(path, ancestor);
+    }
+
+    /**
+     * @param path a normalized path
+     * @param source the normalized path of a moved or copied resource
+     * @param destination the normalized destination path of the moved or copied resource
+     * @return the path of the given path under the destination
+     */
+    static String rebase(String path, String source, String destination)
+    {
+        if (path.equals(source))
+            return destination;
+        String relative = path.substring("/".equals(source) ? 0 : source.length());
+        return "/".equals(destination) ? relative : destination + relative;
+    }
+
+    /**
+     * <p>Parses a WebDAV XML request body, disallowing DTDs and external entities.</p>
+     *
+     * @param content the bytes of the body
+     * @return the parsed document, or null if the body is empty
+     * @throws IOException if the body is not valid XML
+     */
+    static Document parse(byte[] content) throws IOException
+    {
+        if (content.length == 0)
+            return null;
+        try
+        {
+            DocumentBuilderFactory factory = DocumentBuilderFactory.newInstance();
+            factory.setNamespaceAware(true);
+            factory.setFeature(XMLConstants.FEATURE_SECURE_PROCESSING, true);
+            factory.setFeature("http://apache.org/xml/features/disallow-doctype-decl", true);
+            factory.setXIncludeAware(false);
+            factory.setExpandEntityReferences(false);
+            DocumentBuilder builder = factory.newDocumentBuilder();
+            return builder.parse(new ByteArrayInputStream(content));
+        }
+        catch (ParserConfigurationException | SAXException x)
+        {
+            throw new IOException(x);
+        }
+    }
+
+    /**
+     * @param node the node to test
+     * @param localName the local name in the {@code DAV:} namespace
+     * @return whether the node is the given {@code DAV:} element
+     */
+    static boolean isDavElement(Node node, String localName)
+    {
+        return node instanceof Element && NAMESPACE.equals(node.getNamespaceURI()) && localName.equals(node.getLocalName());
+    }
+
+    /**
+     * @param parent the parent element
+     * @return the child elements of the given element
+     */
+    static List<Element> children(Element parent)
+    {
+        List<Element> children = new ArrayList<>();
+        for (Node child = parent.getFirstChild(); child != null; child = child.getNextSibling())
+        {
+            if (child instanceof Element)
+                children.add((Element)child);
+        }
+        return children;
+    }
+
+    /**
+     * @param parent the parent element
+     * @param localName the local name in the {@code DAV:} namespace
+     * @return the first {@code DAV:} child element with the given name, or null
+     */
+    static Element child(Element parent, String localName)
+    {
+        for (Element child : children(parent))
+        {
+            if (isDavElement(child, localName))
+                return child;
+        }
+        return null;
+    }
+
+    /**
+     * @param element an element
+     * @return the qualified name of the element
+     */
+    static QName qname(Element element)
+    {
+        String namespace = element.getNamespaceURI();
+        return new QName(namespace == null ? XMLConstants.NULL_NS_URI : namespace, element.getLocalName());
+    }
+
+    /**
+     * <p>Appends an XML element with the given name and already escaped content.</p>
+     *
+     * @param xml the XML to append to
+     * @param name the element name
+     * @param content the escaped element content, or null for an empty element
+     */
+    static void element(StringBuilder xml, QName name, String content)
+    {
+        boolean dav = NAMESPACE.equals(name.getNamespaceURI());
+        String tag = (dav ? "D:" : "") + name.getLocalPart();
+        xml.append('<').append(tag);
+        if (!dav && !name.getNamespaceURI().isEmpty())
+        {
+            // Non DAV: properties are written in the default namespace, declared locally.
+            xml.append(" xmlns=\"");
+            escape(xml, name.getNamespaceURI());
+            xml.append('"');
+        }
+        if (content == null || content.isEmpty())
+        {
+            xml.append("/>");
+            return;
+        }
+        xml.append('>').append(content).append("</").append(tag).append('>');
+    }
+
+    /**
+     * <p>Appends the given text, escaping the XML special characters.</p>
+     *
+     * @param xml the XML to append to
+     * @param text the text to escape
+     * @return the given XML
+     */
+    static StringBuilder escape(StringBuilder xml, String text)
+    {
+        for (int i = 0; i < text.length(); ++i)
+        {
+            char c = text.charAt(i);
+            switch (c)
+            {
+                case '<':
+                    xml.append("&lt;");
+                    break;
+                case '>':
+                    xml.append("&gt;");
+                    break;
+                case '&':
+                    xml.append("&amp;");
+                    break;
+                case '"':
+                    xml.append("&quot;");
+                    break;
+                case '\'':
+                    xml.append("&apos;");
+                    break;
+                default:
+                    if (c < 0x20 && c != '\t' && c != '\n' && c != '\r')
+                        continue;
+                    xml.append(c);
+                    break;
+            }
+        }
+        return xml;
+    }
+
+    /**
+     * @param text the text to escape
+     * @return the text with the XML special characters escaped
+     */
+    static String escape(String text)
+    {
+        return escape(new StringBuilder(text.length()), text).toString();
+    }
+}
@@ -0,0 +1,146 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.util.Objects;
+import java.util.UUID;
+import java.util.concurrent.TimeUnit;
+
+/**
+ * <p>A WebDAV write lock on a resource, identified by its opaque lock token.</p>
+ * <p>A deep lock (with {@code Depth: infinity}) also locks all the
+ * descendants of a collection.</p>
+ */
+public class WebDavLock
+{
+    private final String token;
+    private final String path;
+    private final boolean exclusive;
+    private final boolean deep;
+    private final String owner;
+    private final long timeout;
+    private final long expiry;
+
+    /**
+     * @param path the path of the locked resource
+     * @param exclusive whether the lock is exclusive or shared
+     * @param deep whether the lock applies to the descendants
+     * @param owner the owner information provided by the client, may be null
+     * @param timeout the lock timeout in seconds
+     */
+    public WebDavLock(String path, boolean exclusive, boolean deep, String owner, long timeout)
+    {
+        this("opaquelocktoken:" + UUID.randomUUID(), path, exclusive, deep, owner, timeout);
+    }
+
+    private WebDavLock(String token, String path, boolean exclusive, boolean deep, String owner, long timeout)
+    {
+        this.token = token;
+        this.path = Objects.requireNonNull(path);
+        this.exclusive = exclusive;
+        this.deep = deep;
+        this.owner = owner;
+        this.timeout = timeout;
+        this.expiry = System.nanoTime() + TimeUnit.SECONDS.toNanos(timeout);
+    }
+
+    /**
+     * @param timeout the new timeout in seconds
+     * @return a copy of this lock, with the same token, that expires after the given timeout
+     */
+    public WebDavLock refresh(long timeout)
+    {
+        return new WebDavLock(token, path, exclusive, deep, owner, timeout);
+    }
+
+    /**
+     * @return the opaque lock token
+     */
+    public String getToken()
+    {
+        return token;
+    }
+
+    /**
+     * @return the path of the locked resource
+     */
+    public String getPath()
+    {
+        return path;
+    }
+
+    /**
+     * @return whether the lock is exclusive
+     */
+    public boolean isExclusive()
+    {
+        return exclusive;
+    }
+
+    /**
+     * @return whether the lock applies to the descendants of the locked resource
+     */
+    public boolean isDeep()
+    {
+        return deep;
+    }
+
+    /**
+     * @return the owner information provided by the client, or null
+     */
+    public String getOwner()
+    {
+        return owner;
+    }
+
+    /**
+     * @return the lock timeout in seconds
+     */
+    public long getTimeout()
+    {
+        return timeout;
+    }
+
+    /**
+     * @return the number of seconds before this lock expires
+     */
+    public long getRemaining()
+    {
+        return Math.max(0, TimeUnit.NANOSECONDS.toSeconds(expiry - System.nanoTime()));
+    }
+
+    /**
+     * @param nanoTime the current {@link System#nanoTime() nano time}
+     * @return whether this lock is expired
+     */
+    public boolean isExpired(long nanoTime)
+    {
+        return nanoTime - expiry >= 0;
+    }
+
+    /**
+     * @param path a resource path
+     * @return whether this lock applies to the resource with the given path
+     */
+    public boolean covers(String path)
+    {
+        return this.path.equals(path) || deep && WebDav.isDescendant(path, this.path);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,path=%s,%s,depth=%s,timeout=%d]", getClass().getSimpleName(), hashCode(), token, path, exclusive ? "exclusive" : "shared", deep ? "infinity" : "0", timeout);
+    }
+}
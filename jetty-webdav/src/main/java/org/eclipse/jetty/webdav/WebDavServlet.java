@@ -0,0 +1,1015 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.io.ByteArrayOutputStream;
+import java.io.File;
+import java.io.IOException;
+import java.io.InputStream;
+import java.nio.file.DirectoryNotEmptyException;
+import java.nio.file.FileVisitResult;
+import java.nio.file.Files;
+import java.nio.file.LinkOption;
+import java.nio.file.Path;
+import java.nio.file.SimpleFileVisitor;
+import java.nio.file.StandardCopyOption;
+import java.nio.file.attribute.BasicFileAttributes;
+import java.util.ArrayList;
+import java.util.Arrays;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.LinkedHashMap;
+import java.util.LinkedHashSet;
+import java.util.List;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Set;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+import javax.servlet.ServletException;
+import javax.servlet.UnavailableException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+import javax.xml.namespace.QName;
+
+import org.eclipse.jetty.http.BadMessageException;
+import org.eclipse.jetty.http.DateGenerator;
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpURI;
+import org.eclipse.jetty.server.ResourceService;
+import org.eclipse.jetty.server.handler.ContextHandler;
+import org.eclipse.jetty.servlet.DefaultServlet;
+import org.eclipse.jetty.util.URIUtil;
+import org.eclipse.jetty.util.resource.Resource;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+import org.w3c.dom.Document;
+import org.w3c.dom.Element;
+
+/**
+ * <p>A {@link DefaultServlet} that implements WebDAV class 1 and 2, as specified by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc4918">RFC 4918</a>,
+ * so that the resources of the context can be browsed and modified by WebDAV clients,
+ * such as the native WebDAV clients of the operating systems.</p>
+ * <p>{@code GET}, {@code HEAD} and {@code POST} requests are served by the
+ * {@link DefaultServlet}, while this servlet implements {@code PUT}, {@code DELETE},
+ * {@code PROPFIND}, {@code PROPPATCH}, {@code MKCOL}, {@code COPY}, {@code MOVE},
+ * {@code LOCK} and {@code UNLOCK}.</p>
+ * <p>Properties are read from the {@link Resource} abstraction, so any resource base
+ * can be browsed, but modifications require resources backed by a file system.
+ * The dead properties set by clients are kept by a {@link PropertyStore},
+ * and the write locks by a {@link LockStore}; both are kept in memory by default.</p>
+ * <p>In addition to the {@link DefaultServlet} init parameters, this servlet supports:</p>
+ * <pre>
+ *  readOnly          If true, only the methods that do not modify the resources
+ *                    are allowed (default false).
+ *  maxLockTimeout    The max timeout, in seconds, of a lock, also used when the
+ *                    client does not specify a timeout (default 3600).
+ *  maxXmlSize        The max size, in bytes, of the XML request bodies (default 65536).
+ * </pre>
+ * <p>The resource cache of the {@link DefaultServlet} must not be configured,
+ * since it would serve stale content after a modification.
+ * Access to the resources must be restricted with security constraints,
+ * since this servlet does not perform any authorization.</p>
+ */
+public class WebDavServlet extends DefaultServlet
+{
+    private static final Logger LOG = LoggerFactory.getLogger(WebDavServlet.class);
+    private static final long serialVersionUID = 6640131544871459365L;
+
+    private static final String DEPTH = "Depth";
+    private static final String DESTINATION = "Destination";
+    private static final String OVERWRITE = "Overwrite";
+    private static final String LOCK_TOKEN = "Lock-Token";
+    private static final String TIMEOUT = "Timeout";
+    private static final String IF = "If";
+    private static final String READ_METHODS = "OPTIONS,GET,HEAD,POST,PROPFIND";
+    private static final String WRITE_METHODS = ",PUT,DELETE,PROPPATCH,MKCOL,COPY,MOVE,LOCK,UNLOCK";
+    private static final Pattern LOCK_TOKEN_PATTERN = Pattern.compile("<(opaquelocktoken:[^>]+)>");
+    private static final int INFINITY = Integer.MAX_VALUE;
+    private static final QName DISPLAY_NAME = dav("displayname");
+    private static final QName RESOURCE_TYPE = dav("resourcetype");
+    private static final QName CONTENT_LENGTH = dav("getcontentlength");
+    private static final QName CONTENT_TYPE = dav("getcontenttype");
+    private static final QName ETAG = dav("getetag");
+    private static final QName LAST_MODIFIED = dav("getlastmodified");
+    private static final QName SUPPORTED_LOCK = dav("supportedlock");
+    private static final QName LOCK_DISCOVERY = dav("lockdiscovery");
+    private static final List<QName> LIVE_PROPERTIES = List.of(DISPLAY_NAME, RESOURCE_TYPE, CONTENT_LENGTH, CONTENT_TYPE, ETAG, LAST_MODIFIED, SUPPORTED_LOCK, LOCK_DISCOVERY);
+    private static final String SUPPORTED_LOCKS =
+        "<D:lockentry><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockentry>" +
+        "<D:lockentry><D:lockscope><D:shared/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockentry>";
+
+    private final ResourceService resourceService;
+    private final PropertyStore propertyStore;
+    private final LockStore lockStore;
+    private ContextHandler contextHandler;
+    private boolean readOnly;
+    private long maxLockTimeout = 3600;
+    private int maxXmlSize = 64 * 1024;
+
+    public WebDavServlet()
+    {
+        this(new InMemoryPropertyStore(), new InMemoryLockStore());
+    }
+
+    public WebDavServlet(PropertyStore propertyStore, LockStore lockStore)
+    {
+        this(new ResourceService(), propertyStore, lockStore);
+    }
+
+    private WebDavServlet(ResourceService resourceService, PropertyStore propertyStore, LockStore lockStore)
+    {
+        super(resourceService);
+        this.resourceService = resourceService;
+        this.propertyStore = Objects.requireNonNull(propertyStore);
+        this.lockStore = Objects.requireNonNull(lockStore);
+    }
+
+    private static QName dav(String localName)
+    {
+        return new QName(WebDav.NAMESPACE, localName);
+    }
+
+    /**
+     * @return the store of the dead properties
+     */
+    public PropertyStore getPropertyStore()
+    {
+        return propertyStore;
+    }
+
+    /**
+     * @return the store of the locks
+     */
+    public LockStore getLockStore()
+    {
+        return lockStore;
+    }
+
+    /**
+     * @return whether only the methods that do not modify the resources are allowed
+     */
+    public boolean isReadOnly()
+    {
+        return readOnly;
+    }
+
+    @Override
+    public void init() throws UnavailableException
+    {
+        super.init();
+        contextHandler = ContextHandler.getContextHandler(getServletContext());
+        String value = getInitParameter("readOnly");
+        if (value != null)
+            readOnly = Boolean.parseBoolean(value);
+        value = getInitParameter("maxLockTimeout");
+        if (value != null)
+            maxLockTimeout = Long.parseLong(value);
+        value = getInitParameter("maxXmlSize");
+        if (value != null)
+            maxXmlSize = Integer.parseInt(value);
+    }
+
+    @Override
+    protected void service(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        HttpMethod method = HttpMethod.fromString(request.getMethod());
+        if (method == null)
+        {
+            super.service(request, response);
+            return;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("{} {}", method, request.getRequestURI());
+
+        switch (method)
+        {
+            case PROPFIND:
+                doPropfind(request, response);
+                break;
+            case PROPPATCH:
+                doProppatch(request, response);
+                break;
+            case MKCOL:
+                doMkcol(request, response);
+                break;
+            case COPY:
+                doCopyOrMove(request, response, false);
+                break;
+            case MOVE:
+                doCopyOrMove(request, response, true);
+                break;
+            case LOCK:
+                doLock(request, response);
+                break;
+            case UNLOCK:
+                doUnlock(request, response);
+                break;
+            default:
+                super.service(request, response);
+                break;
+        }
+    }
+
+    @Override
+    protected void doOptions(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        response.setHeader("DAV", "1, 2");
+        response.setHeader(HttpHeader.ALLOW.asString(), readOnly ? READ_METHODS : READ_METHODS + WRITE_METHODS);
+        response.setHeader("MS-Author-Via", "DAV");
+    }
+
+    @Override
+    protected void doPut(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        if (!checkWritable(request, response, path, false))
+            return;
+
+        Path file = getFile(path);
+        if (file == null)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+        if (Files.isDirectory(file))
+        {
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+        if (!Files.isDirectory(file.getParent()))
+        {
+            response.sendError(HttpStatus.CONFLICT_409);
+            return;
+        }
+
+        boolean exists = Files.exists(file);
+        if (!exists && !checkLocks(request, response, WebDav.parent(path), false))
+            return;
+
+        try (InputStream input = request.getInputStream())
+        {
+            Files.copy(input, file, StandardCopyOption.REPLACE_EXISTING);
+        }
+        catch (IOException x)
+        {
+            if (!exists)
+                Files.deleteIfExists(file);
+            throw x;
+        }
+        response.setStatus(exists ? HttpStatus.NO_CONTENT_204 : HttpStatus.CREATED_201);
+    }
+
+    @Override
+    protected void doDelete(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        if ("/".equals(path))
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+        if (!checkWritable(request, response, path, true) || !checkLocks(request, response, WebDav.parent(path), false))
+            return;
+
+        Path file = getFile(path);
+        if (file == null || !Files.exists(file, LinkOption.NOFOLLOW_LINKS))
+        {
+            response.sendError(file == null ? HttpStatus.FORBIDDEN_403 : HttpStatus.NOT_FOUND_404);
+            return;
+        }
+
+        delete(file);
+        propertyStore.delete(path);
+        lockStore.removeLocks(path);
+        response.setStatus(HttpStatus.NO_CONTENT_204);
+    }
+
+    protected void doPropfind(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        Resource resource = getResource(path);
+        if (resource == null || !resource.exists())
+        {
+            response.sendError(HttpStatus.NOT_FOUND_404);
+            return;
+        }
+
+        int depth = getDepth(request, INFINITY);
+        if (depth == INFINITY)
+        {
+            sendError(response, HttpStatus.FORBIDDEN_403, "propfind-finite-depth");
+            return;
+        }
+
+        // A null list of names means all properties, an empty list means property names.
+        List<QName> names = null;
+        Document document = parseXML(request);
+        if (document != null)
+        {
+            Element propfind = document.getDocumentElement();
+            if (!WebDav.isDavElement(propfind, "propfind"))
+                throw new BadMessageException("Invalid PROPFIND body");
+            Element prop = WebDav.child(propfind, "prop");
+            if (prop != null)
+            {
+                names = new ArrayList<>();
+                for (Element child : WebDav.children(prop))
+                {
+                    names.add(WebDav.qname(child));
+                }
+            }
+            else if (WebDav.child(propfind, "propname") != null)
+            {
+                names = List.of();
+            }
+        }
+
+        MultiStatus multiStatus = new MultiStatus();
+        propfind(request, multiStatus, path, resource, names);
+        if (depth > 0 && resource.isDirectory())
+        {
+            String[] children = resource.list();
+            if (children != null)
+            {
+                Arrays.sort(children);
+                for (String child : children)
+                {
+                    String childPath = WebDav.normalize(URIUtil.addPaths(path, child));
+                    if (contextHandler.isProtectedTarget(childPath))
+                        continue;
+                    Resource childResource = getResource(childPath);
+                    if (childResource != null && childResource.exists())
+                        propfind(request, multiStatus, childPath, childResource, names);
+                }
+            }
+        }
+        multiStatus.send(response);
+    }
+
+    private void propfind(HttpServletRequest request, MultiStatus multiStatus, String path, Resource resource, List<QName> names)
+    {
+        List<String> found = new ArrayList<>();
+        List<String> missing = new ArrayList<>();
+        Map<QName, String> deadProperties = propertyStore.getProperties(path);
+        String href = getHref(request, path, resource.isDirectory());
+
+        if (names == null || names.isEmpty())
+        {
+            boolean values = names == null;
+            for (QName name : LIVE_PROPERTIES)
+            {
+                String value = getLiveProperty(request, name, path, resource);
+                if (value != null)
+                    found.add(property(name, values ? value : null));
+            }
+            deadProperties.forEach((name, value) -> found.add(property(name, values ? WebDav.escape(value) : null)));
+        }
+        else
+        {
+            for (QName name : names)
+            {
+                String value = getLiveProperty(request, name, path, resource);
+                if (value == null && deadProperties.containsKey(name))
+                    value = WebDav.escape(deadProperties.get(name));
+                if (value == null)
+                    missing.add(property(name, null));
+                else
+                    found.add(property(name, value));
+            }
+        }
+
+        multiStatus.startResponse(href);
+        multiStatus.propstat(HttpStatus.OK_200, found);
+        multiStatus.propstat(HttpStatus.NOT_FOUND_404, missing);
+        multiStatus.endResponse();
+    }
+
+    /**
+     * @param request the request
+     * @param name the property name
+     * @param path the resource path
+     * @param resource the resource
+     * @return the escaped XML value of the live property, or null if the resource does not have it
+     */
+    protected String getLiveProperty(HttpServletRequest request, QName name, String path, Resource resource)
+    {
+        boolean directory = resource.isDirectory();
+        if (DISPLAY_NAME.equals(name))
+            return WebDav.escape(path.substring(path.lastIndexOf('/') + 1));
+        if (RESOURCE_TYPE.equals(name))
+            return directory ? "<D:collection/>" : "";
+        if (CONTENT_LENGTH.equals(name))
+            return directory ? null : String.valueOf(resource.length());
+        if (CONTENT_TYPE.equals(name))
+        {
+            if (directory)
+                return null;
+            String contentType = contextHandler.getMimeTypes().getMimeByExtension(path);
+            return WebDav.escape(contentType == null ? "application/octet-stream" : contentType);
+        }
+        if (ETAG.equals(name))
+            return directory ? null : WebDav.escape(resource.getWeakETag());
+        if (LAST_MODIFIED.equals(name))
+            return DateGenerator.formatDate(resource.lastModified());
+        if (SUPPORTED_LOCK.equals(name))
+            return SUPPORTED_LOCKS;
+        if (LOCK_DISCOVERY.equals(name))
+        {
+            StringBuilder xml = new StringBuilder();
+            for (WebDavLock lock : lockStore.getLocks(path, false))
+            {
+                activeLock(request, xml, lock);
+            }
+            return xml.toString();
+        }
+        return null;
+    }
+
+    protected void doProppatch(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        Resource resource = getResource(path);
+        if (resource == null || !resource.exists())
+        {
+            response.sendError(HttpStatus.NOT_FOUND_404);
+            return;
+        }
+        if (!checkWritable(request, response, path, false))
+            return;
+
+        Document document = parseXML(request);
+        if (document == null || !WebDav.isDavElement(document.getDocumentElement(), "propertyupdate"))
+            throw new BadMessageException("Invalid PROPPATCH body");
+
+        // Instructions are processed in document order, so the last one wins.
+        Map<QName, String> set = new LinkedHashMap<>();
+        Set<QName> remove = new LinkedHashSet<>();
+        for (Element instruction : WebDav.children(document.getDocumentElement()))
+        {
+            boolean isSet = WebDav.isDavElement(instruction, "set");
+            if (!isSet && !WebDav.isDavElement(instruction, "remove"))
+                continue;
+            Element prop = WebDav.child(instruction, "prop");
+            if (prop == null)
+                continue;
+            for (Element property : WebDav.children(prop))
+            {
+                QName name = WebDav.qname(property);
+                if (isSet)
+                {
+                    remove.remove(name);
+                    set.put(name, property.getTextContent());
+                }
+                else
+                {
+                    set.remove(name);
+                    remove.add(name);
+                }
+            }
+        }
+
+        // Live properties are protected, and the update is atomic.
+        Set<QName> names = new LinkedHashSet<>(set.keySet());
+        names.addAll(remove);
+        boolean failed = names.stream().anyMatch(LIVE_PROPERTIES::contains);
+        Map<Integer, List<String>> statuses = new HashMap<>();
+        for (QName name : names)
+        {
+            int status = !failed ? HttpStatus.OK_200 : LIVE_PROPERTIES.contains(name) ? HttpStatus.FORBIDDEN_403 : HttpStatus.FAILED_DEPENDENCY_424;
+            statuses.computeIfAbsent(status, s -> new ArrayList<>()).add(property(name, null));
+        }
+        if (!failed)
+            propertyStore.update(path, set, remove);
+
+        MultiStatus multiStatus = new MultiStatus();
+        multiStatus.startResponse(getHref(request, path, resource.isDirectory()));
+        statuses.forEach(multiStatus::propstat);
+        multiStatus.endResponse();
+        multiStatus.send(response);
+    }
+
+    protected void doMkcol(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        if (!checkWritable(request, response, path, false))
+            return;
+
+        if (request.getContentLengthLong() > 0 || request.getHeader(HttpHeader.TRANSFER_ENCODING.asString()) != null)
+        {
+            response.sendError(HttpStatus.UNSUPPORTED_MEDIA_TYPE_415);
+            return;
+        }
+
+        Path directory = getFile(path);
+        if (directory == null)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+        if (Files.exists(directory))
+        {
+            response.sendError(HttpStatus.METHOD_NOT_ALLOWED_405);
+            return;
+        }
+        if (!Files.isDirectory(directory.getParent()))
+        {
+            response.sendError(HttpStatus.CONFLICT_409);
+            return;
+        }
+        if (!checkLocks(request, response, WebDav.parent(path), false))
+            return;
+
+        Files.createDirectory(directory);
+        response.setStatus(HttpStatus.CREATED_201);
+    }
+
+    protected void doCopyOrMove(HttpServletRequest request, HttpServletResponse response, boolean move) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        Resource resource = getResource(path);
+        if (resource == null || !resource.exists())
+        {
+            response.sendError(HttpStatus.NOT_FOUND_404);
+            return;
+        }
+        if (readOnly)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+
+        String destination = getDestination(request);
+        if (destination == null)
+        {
+            response.sendError(HttpStatus.BAD_GATEWAY_502);
+            return;
+        }
+        // Cannot copy or move a resource to itself, to a descendant, or over an ancestor.
+        if (contextHandler.isProtectedTarget(destination) || WebDav.isSelfOrDescendant(destination, path) || WebDav.isDescendant(path, destination))
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+
+        int depth = getDepth(request, INFINITY);
+        if (depth == 1 || move && depth != INFINITY)
+            throw new BadMessageException("Invalid Depth");
+
+        if (move && (!checkLocks(request, response, path, true) || !checkLocks(request, response, WebDav.parent(path), false)))
+            return;
+        if (!checkLocks(request, response, destination, true) || !checkLocks(request, response, WebDav.parent(destination), false))
+            return;
+
+        Path source = getFile(path);
+        Path target = getFile(destination);
+        if (source == null || target == null)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+        if (!Files.isDirectory(target.getParent()))
+        {
+            response.sendError(HttpStatus.CONFLICT_409);
+            return;
+        }
+
+        boolean exists = Files.exists(target, LinkOption.NOFOLLOW_LINKS);
+        if (exists)
+        {
+            if ("F".equalsIgnoreCase(request.getHeader(OVERWRITE)))
+            {
+                response.sendError(HttpStatus.PRECONDITION_FAILED_412);
+                return;
+            }
+            delete(target);
+            propertyStore.delete(destination);
+            lockStore.removeLocks(destination);
+        }
+
+        if (move)
+        {
+            try
+            {
+                Files.move(source, target);
+            }
+            catch (DirectoryNotEmptyException x)
+            {
+                // The directory cannot be renamed, for example across file systems.
+                copy(source, target, INFINITY);
+                delete(source);
+            }
+            propertyStore.move(path, destination);
+            lockStore.removeLocks(path);
+        }
+        else
+        {
+            copy(source, target, depth);
+            propertyStore.copy(path, destination);
+        }
+        response.setStatus(exists ? HttpStatus.NO_CONTENT_204 : HttpStatus.CREATED_201);
+    }
+
+    protected void doLock(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        if (readOnly)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+
+        long timeout = getLockTimeout(request);
+        Document document = parseXML(request);
+        WebDavLock lock;
+        boolean created = false;
+        if (document == null)
+        {
+            // Refresh an existing lock.
+            lock = null;
+            for (String token : getSubmittedLockTokens(request))
+            {
+                boolean applies = lockStore.getLocks(path, false).stream().anyMatch(l -> l.getToken().equals(token));
+                if (applies)
+                {
+                    lock = lockStore.refresh(token, timeout);
+                    break;
+                }
+            }
+            if (lock == null)
+            {
+                sendError(response, HttpStatus.PRECONDITION_FAILED_412, "lock-token-matches-request-uri");
+                return;
+            }
+        }
+        else
+        {
+            Element lockInfo = document.getDocumentElement();
+            if (!WebDav.isDavElement(lockInfo, "lockinfo"))
+                throw new BadMessageException("Invalid LOCK body");
+            Element lockScope = WebDav.child(lockInfo, "lockscope");
+            Element lockType = WebDav.child(lockInfo, "locktype");
+            if (lockScope == null || lockType == null || WebDav.child(lockType, "write") == null)
+                throw new BadMessageException("Invalid LOCK body");
+            boolean exclusive = WebDav.child(lockScope, "exclusive") != null;
+            if (!exclusive && WebDav.child(lockScope, "shared") == null)
+                throw new BadMessageException("Invalid LOCK body");
+            Element ownerElement = WebDav.child(lockInfo, "owner");
+            String owner = ownerElement == null ? null : ownerElement.getTextContent().trim();
+
+            int depth = getDepth(request, INFINITY);
+            if (depth == 1)
+                throw new BadMessageException("Invalid Depth");
+
+            Path file = getFile(path);
+            if (file == null)
+            {
+                response.sendError(HttpStatus.FORBIDDEN_403);
+                return;
+            }
+            boolean exists = Files.exists(file);
+            if (!exists && !Files.isDirectory(file.getParent()))
+            {
+                response.sendError(HttpStatus.CONFLICT_409);
+                return;
+            }
+            if (!exists && !checkLocks(request, response, WebDav.parent(path), false))
+                return;
+
+            lock = new WebDavLock(path, exclusive, depth == INFINITY, owner, timeout);
+            if (!lockStore.lock(lock))
+            {
+                sendError(response, HttpStatus.LOCKED_423, "no-conflicting-lock");
+                return;
+            }
+
+            // Locking an unmapped URL creates an empty resource.
+            if (!exists)
+            {
+                try
+                {
+                    Files.createFile(file);
+                    created = true;
+                }
+                catch (IOException x)
+                {
+                    lockStore.unlock(lock.getToken());
+                    throw x;
+                }
+            }
+        }
+
+        StringBuilder xml = new StringBuilder();
+        xml.append(WebDav.XML_DECLARATION).append("<D:prop xmlns:D=\"DAV:\"><D:lockdiscovery>");
+        activeLock(request, xml, lock);
+        xml.append("</D:lockdiscovery></D:prop>");
+        response.setHeader(LOCK_TOKEN, "<" + lock.getToken() + ">");
+        MultiStatus.send(response, created ? HttpStatus.CREATED_201 : HttpStatus.OK_200, xml.toString());
+    }
+
+    protected void doUnlock(HttpServletRequest request, HttpServletResponse response) throws ServletException, IOException
+    {
+        String path = getPathInContext(request);
+        String header = request.getHeader(LOCK_TOKEN);
+        if (header == null)
+            throw new BadMessageException("Missing Lock-Token");
+        String token = header.trim();
+        if (token.startsWith("<") && token.endsWith(">"))
+            token = token.substring(1, token.length() - 1);
+
+        String lockToken = token;
+        boolean applies = lockStore.getLocks(path, false).stream().anyMatch(lock -> lock.getToken().equals(lockToken));
+        if (!applies)
+        {
+            sendError(response, HttpStatus.CONFLICT_409, "lock-token-matches-request-uri");
+            return;
+        }
+        lockStore.unlock(token);
+        response.setStatus(HttpStatus.NO_CONTENT_204);
+    }
+
+    private void activeLock(HttpServletRequest request, StringBuilder xml, WebDavLock lock)
+    {
+        xml.append("<D:activelock><D:locktype><D:write/></D:locktype><D:lockscope>");
+        xml.append(lock.isExclusive() ? "<D:exclusive/>" : "<D:shared/>");
+        xml.append("</D:lockscope><D:depth>").append(lock.isDeep() ? "infinity" : "0").append("</D:depth>");
+        if (lock.getOwner() != null)
+            WebDav.escape(xml.append("<D:owner>"), lock.getOwner()).append("</D:owner>");
+        xml.append("<D:timeout>Second-").append(lock.getRemaining()).append("</D:timeout>");
+        WebDav.escape(xml.append("<D:locktoken><D:href>"), lock.getToken()).append("</D:href></D:locktoken>");
+        Resource resource = getResource(lock.getPath());
+        boolean directory = resource != null && resource.isDirectory();
+        WebDav.escape(xml.append("<D:lockroot><D:href>"), getHref(request, lock.getPath(), directory)).append("</D:href></D:lockroot>");
+        xml.append("</D:activelock>");
+    }
+
+    private static String property(QName name, String value)
+    {
+        StringBuilder xml = new StringBuilder();
+        WebDav.element(xml, name, value);
+        return xml.toString();
+    }
+
+    /**
+     * <p>Checks that the resource can be modified: that this servlet is not read-only,
+     * and that the lock tokens of the locks of the resource have been submitted.</p>
+     *
+     * @param request the request
+     * @param response the response
+     * @param path the resource path
+     * @param descendants whether the descendants of the resource are also modified
+     * @return whether the resource can be modified, otherwise an error response has been sent
+     * @throws IOException if the error response cannot be sent
+     */
+    private boolean checkWritable(HttpServletRequest request, HttpServletResponse response, String path, boolean descendants) throws IOException
+    {
+        if (readOnly)
+        {
+            response.sendError(HttpStatus.FORBIDDEN_403);
+            return false;
+        }
+        return checkLocks(request, response, path, descendants);
+    }
+
+    private boolean checkLocks(HttpServletRequest request, HttpServletResponse response, String path, boolean descendants) throws IOException
+    {
+        if (path == null)
+            return true;
+        List<WebDavLock> locks = lockStore.getLocks(path, descendants);
+        if (locks.isEmpty())
+            return true;
+
+        // The token of one of the locks of each locked resource must be submitted.
+        Set<String> tokens = getSubmittedLockTokens(request);
+        Map<String, Boolean> locked = new HashMap<>();
+        for (WebDavLock lock : locks)
+        {
+            locked.merge(lock.getPath(), tokens.contains(lock.getToken()), Boolean::logicalOr);
+        }
+        for (Map.Entry<String, Boolean> entry : locked.entrySet())
+        {
+            if (!entry.getValue())
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Locked {} by {}", path, entry.getKey());
+                sendError(response, HttpStatus.LOCKED_423, "lock-token-submitted");
+                return false;
+            }
+        }
+        return true;
+    }
+
+    /**
+     * <p>Returns the lock tokens submitted in the {@code If} request header.</p>
+     * <p>The tokens are collected regardless of the resources they are tagged with,
+     * and of the other conditions of the header.</p>
+     *
+     * @param request the request
+     * @return the submitted lock tokens
+     */
+    private Set<String> getSubmittedLockTokens(HttpServletRequest request)
+    {
+        Set<String> tokens = new HashSet<>();
+        String header = request.getHeader(IF);
+        if (header != null)
+        {
+            Matcher matcher = LOCK_TOKEN_PATTERN.matcher(header);
+            while (matcher.find())
+            {
+                tokens.add(matcher.group(1));
+            }
+        }
+        return tokens;
+    }
+
+    private void sendError(HttpServletResponse response, int status, String condition) throws IOException
+    {
+        String xml = WebDav.XML_DECLARATION + "<D:error xmlns:D=\"DAV:\"><D:" + condition + "/></D:error>";
+        MultiStatus.send(response, status, xml);
+    }
+
+    private String getPathInContext(HttpServletRequest request)
+    {
+        String servletPath = resourceService.isPathInfoOnly() ? "/" : request.getServletPath();
+        return WebDav.normalize(URIUtil.addPaths(servletPath, request.getPathInfo()));
+    }
+
+    private String getPathPrefix(HttpServletRequest request)
+    {
+        return request.getContextPath() + (resourceService.isPathInfoOnly() ? request.getServletPath() : "");
+    }
+
+    private String getHref(HttpServletRequest request, String path, boolean directory)
+    {
+        String href = URIUtil.encodePath(URIUtil.addPaths(getPathPrefix(request), path));
+        if (directory && !href.endsWith("/"))
+            href += "/";
+        return href;
+    }
+
+    /**
+     * @param request the request
+     * @return the path in context of the {@code Destination} header, or null if it does not belong to this servlet
+     */
+    private String getDestination(HttpServletRequest request)
+    {
+        String header = request.getHeader(DESTINATION);
+        if (header == null)
+            throw new BadMessageException("Missing Destination");
+        HttpURI uri = HttpURI.from(header);
+        if (uri.getHost() != null && !uri.getHost().equalsIgnoreCase(request.getServerName()))
+            return null;
+        String decodedPath = uri.getDecodedPath();
+        if (decodedPath == null)
+            throw new BadMessageException("Invalid Destination");
+        String prefix = getPathPrefix(request);
+        if (!prefix.isEmpty() && !decodedPath.equals(prefix) && !decodedPath.startsWith(prefix + "/"))
+            return null;
+        return WebDav.normalize(decodedPath.substring(prefix.length()));
+    }
+
+    private Path getFile(String path)
+    {
+        try
+        {
+            Resource resource = getResource(path);
+            File file = resource == null ? null : resource.getFile();
+            return file == null ? null : file.toPath();
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("No file for {}", path, x);
+            return null;
+        }
+    }
+
+    private int getDepth(HttpServletRequest request, int defaultDepth)
+    {
+        String depth = request.getHeader(DEPTH);
+        if (depth == null)
+            return defaultDepth;
+        switch (depth.trim().toLowerCase())
+        {
+            case "0":
+                return 0;
+            case "1":
+                return 1;
+            case "infinity":
+                return INFINITY;
+            default:
+                throw new BadMessageException("Invalid Depth");
+        }
+    }
+
+    private long getLockTimeout(HttpServletRequest request)
+    {
+        String header = request.getHeader(TIMEOUT);
+        if (header != null)
+        {
+            for (String value : header.split(","))
+            {
+                value = value.trim();
+                if (value.regionMatches(true, 0, "Second-", 0, 7))
+                {
+                    try
+                    {
+                        return Math.min(maxLockTimeout, Math.max(1, Long.parseLong(value.substring(7))));
+                    }
+                    catch (NumberFormatException x)
+                    {
+                        // Try the next value.
+                    }
+                }
+            }
+        }
+        return maxLockTimeout;
+    }
+
+    private Document parseXML(HttpServletRequest request) throws IOException
+    {
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        try (InputStream input = request.getInputStream())
+        {
+            byte[] buffer = new byte[4096];
+            int read;
+            while ((read = input.read(buffer)) >= 0)
+            {
+                if (output.size() + read > maxXmlSize)
+                    throw new BadMessageException(HttpStatus.PAYLOAD_TOO_LARGE_413);
+                output.write(buffer, 0, read);
+            }
+        }
+        try
+        {
+            return WebDav.parse(output.toByteArray());
+        }
+        catch (IOException x)
+        {
+            throw new BadMessageException(HttpStatus.BAD_REQUEST_400, "Invalid XML", x);
+        }
+    }
+
+    private static void copy(Path source, Path target, int depth) throws IOException
+    {
+        if (depth == 0 || !Files.isDirectory(source, LinkOption.NOFOLLOW_LINKS))
+        {
+            Files.copy(source, target, StandardCopyOption.COPY_ATTRIBUTES);
+            return;
+        }
+        Files.walkFileTree(source, new SimpleFileVisitor<>()
+        {
+            @Override
+            public FileVisitResult preVisitDirectory(Path dir, BasicFileAttributes attrs) throws IOException
+            {
+                Files.createDirectory(target.resolve(source.relativize(dir)));
+                return FileVisitResult.CONTINUE;
+            }
+
+            @Override
+            public FileVisitResult visitFile(Path file, BasicFileAttributes attrs) throws IOException
+            {
+                Files.copy(file, target.resolve(source.relativize(file)), StandardCopyOption.COPY_ATTRIBUTES);
+                return FileVisitResult.CONTINUE;
+            }
+        });
+    }
+
+    private static void delete(Path path) throws IOException
+    {
+        // Does not follow symbolic links, so only the links are deleted.
+        Files.walkFileTree(path, new SimpleFileVisitor<>()
+        {
+            @Override
+            public FileVisitResult visitFile(Path file, BasicFileAttributes attrs) throws IOException
+            {
+                Files.delete(file);
+                return FileVisitResult.CONTINUE;
+            }
+
+            @Override
+            public FileVisitResult postVisitDirectory(Path dir, IOException x) throws IOException
+            {
+                if (x != null)
+                    throw x;
+                Files.delete(dir);
+                return FileVisitResult.CONTINUE;
+            }
+        });
+    }
+}
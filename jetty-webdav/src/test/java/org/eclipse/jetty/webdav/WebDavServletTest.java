@@ -0,0 +1,293 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webdav;
+
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+
+import org.eclipse.jetty.http.HttpHeader;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.HttpTester;
+import org.eclipse.jetty.server.LocalConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.servlet.ServletHolder;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.resource.PathResource;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+
+@ExtendWith(WorkDirExtension.class)
+public class WebDavServletTest
+{
+    public WorkDir workDir;
+    private Path docRoot;
+    private Server server;
+    private LocalConnector connector;
+    private ServletHolder holder;
+
+    @BeforeEach
+    public void prepare() throws Exception
+    {
+        docRoot = workDir.getEmptyPathDir().resolve("docroot");
+        Files.createDirectories(docRoot);
+
+        server = new Server();
+        connector = new LocalConnector(server);
+        server.addConnector(connector);
+
+        ServletContextHandler context = new ServletContextHandler();
+        context.setContextPath("/context");
+        context.setBaseResource(new PathResource(docRoot));
+        holder = new ServletHolder(new WebDavServlet());
+        context.addServlet(holder, "/*");
+        server.setHandler(context);
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        server.stop();
+    }
+
+    private HttpTester.Response request(String method, String uri, String headers, String body) throws Exception
+    {
+        byte[] content = body == null ? new byte[0] : body.getBytes(StandardCharsets.UTF_8);
+        String request = method + " " + uri + " HTTP/1.1\r\n" +
+            "Host: localhost\r\n" +
+            (headers == null ? "" : headers) +
+            "Content-Length: " + content.length + "\r\n" +
+            "Connection: close\r\n" +
+            "\r\n" +
+            (body == null ? "" : body);
+        return HttpTester.parseResponse(connector.getResponse(request));
+    }
+
+    @Test
+    public void testOptions() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request("OPTIONS", "/context/", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.get("DAV"), is("1, 2"));
+        assertThat(response.get(HttpHeader.ALLOW), containsString("PROPFIND"));
+        assertThat(response.get(HttpHeader.ALLOW), containsString("LOCK"));
+    }
+
+    @Test
+    public void testPutGetDelete() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request("PUT", "/context/file.txt", null, "hello");
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        assertThat(Files.readString(docRoot.resolve("file.txt")), is("hello"));
+
+        response = request("PUT", "/context/file.txt", null, "world");
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+
+        response = request("GET", "/context/file.txt", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), is("world"));
+
+        response = request("PUT", "/context/missing/file.txt", null, "hello");
+        assertThat(response.getStatus(), is(HttpStatus.CONFLICT_409));
+
+        response = request("DELETE", "/context/file.txt", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+        assertThat(Files.exists(docRoot.resolve("file.txt")), is(false));
+    }
+
+    @Test
+    public void testMkcolAndPropfind() throws Exception
+    {
+        server.start();
+
+        HttpTester.Response response = request("MKCOL", "/context/dir", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        assertThat(Files.isDirectory(docRoot.resolve("dir")), is(true));
+        response = request("MKCOL", "/context/dir", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.METHOD_NOT_ALLOWED_405));
+        response = request("MKCOL", "/context/a/b", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.CONFLICT_409));
+
+        Files.writeString(docRoot.resolve("dir/file one.txt"), "content");
+
+        response = request("PROPFIND", "/context/dir", "Depth: 1\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.MULTI_STATUS_207));
+        String content = response.getContent();
+        assertThat(content, containsString("<D:href>/context/dir/</D:href>"));
+        assertThat(content, containsString("<D:href>/context/dir/file%20one.txt</D:href>"));
+        assertThat(content, containsString("<D:collection/>"));
+        assertThat(content, containsString("<D:getcontentlength>7</D:getcontentlength>"));
+
+        String propfind = "<?xml version=\"1.0\"?><D:propfind xmlns:D=\"DAV:\"><D:prop><D:getcontentlength/><D:missing/></D:prop></D:propfind>";
+        response = request("PROPFIND", "/context/dir/file%20one.txt", "Depth: 0\r\n", propfind);
+        assertThat(response.getStatus(), is(HttpStatus.MULTI_STATUS_207));
+        content = response.getContent();
+        assertThat(content, containsString("<D:getcontentlength>7</D:getcontentlength>"));
+        assertThat(content, containsString("<D:missing/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status>"));
+        assertThat(content, not(containsString("getlastmodified")));
+
+        response = request("PROPFIND", "/context/dir", "Depth: infinity\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+    }
+
+    @Test
+    public void testProppatch() throws Exception
+    {
+        Files.writeString(docRoot.resolve("file.txt"), "content");
+        server.start();
+
+        String proppatch = "<?xml version=\"1.0\"?>" +
+            "<D:propertyupdate xmlns:D=\"DAV:\" xmlns:Z=\"urn:test\">" +
+            "<D:set><D:prop><Z:author>Jane &amp; John</Z:author></D:prop></D:set>" +
+            "</D:propertyupdate>";
+        HttpTester.Response response = request("PROPPATCH", "/context/file.txt", null, proppatch);
+        assertThat(response.getStatus(), is(HttpStatus.MULTI_STATUS_207));
+        assertThat(response.getContent(), containsString("HTTP/1.1 200 OK"));
+
+        String propfind = "<?xml version=\"1.0\"?><D:propfind xmlns:D=\"DAV:\"><D:prop><Z:author xmlns:Z=\"urn:test\"/></D:prop></D:propfind>";
+        response = request("PROPFIND", "/context/file.txt", "Depth: 0\r\n", propfind);
+        assertThat(response.getContent(), containsString("<author xmlns=\"urn:test\">Jane &amp; John</author>"));
+
+        // Live properties are protected, so the whole update fails.
+        proppatch = "<?xml version=\"1.0\"?>" +
+            "<D:propertyupdate xmlns:D=\"DAV:\" xmlns:Z=\"urn:test\">" +
+            "<D:set><D:prop><D:getetag>x</D:getetag><Z:title>Title</Z:title></D:prop></D:set>" +
+            "</D:propertyupdate>";
+        response = request("PROPPATCH", "/context/file.txt", null, proppatch);
+        assertThat(response.getStatus(), is(HttpStatus.MULTI_STATUS_207));
+        assertThat(response.getContent(), containsString("HTTP/1.1 403 Forbidden"));
+        assertThat(response.getContent(), containsString("HTTP/1.1 424 Failed Dependency"));
+
+        response = request("MOVE", "/context/file.txt", "Destination: http://localhost/context/moved.txt\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        response = request("PROPFIND", "/context/moved.txt", "Depth: 0\r\n", propfind);
+        assertThat(response.getContent(), containsString("Jane &amp; John"));
+    }
+
+    @Test
+    public void testCopyMove() throws Exception
+    {
+        Files.createDirectories(docRoot.resolve("src/sub"));
+        Files.writeString(docRoot.resolve("src/sub/file.txt"), "content");
+        Files.writeString(docRoot.resolve("other.txt"), "other");
+        server.start();
+
+        HttpTester.Response response = request("COPY", "/context/src", "Destination: /context/copy\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        assertThat(Files.readString(docRoot.resolve("copy/sub/file.txt")), is("content"));
+
+        response = request("COPY", "/context/other.txt", "Destination: /context/copy/sub/file.txt\r\nOverwrite: F\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.PRECONDITION_FAILED_412));
+        response = request("COPY", "/context/other.txt", "Destination: /context/copy/sub/file.txt\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+        assertThat(Files.readString(docRoot.resolve("copy/sub/file.txt")), is("other"));
+
+        response = request("MOVE", "/context/src", "Destination: /context/src/sub/inside\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.FORBIDDEN_403));
+        response = request("MOVE", "/context/src", "Destination: http://other.com/context/elsewhere\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.BAD_GATEWAY_502));
+
+        response = request("MOVE", "/context/src", "Destination: /context/moved\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        assertThat(Files.exists(docRoot.resolve("src")), is(false));
+        assertThat(Files.readString(docRoot.resolve("moved/sub/file.txt")), is("content"));
+    }
+
+    @Test
+    public void testLocks() throws Exception
+    {
+        server.start();
+
+        String lockInfo = "<?xml version=\"1.0\"?>" +
+            "<D:lockinfo xmlns:D=\"DAV:\">" +
+            "<D:lockscope><D:exclusive/></D:lockscope>" +
+            "<D:locktype><D:write/></D:locktype>" +
+            "<D:owner><D:href>mailto:jane@example.com</D:href></D:owner>" +
+            "</D:lockinfo>";
+        // Locking an unmapped URL creates an empty resource.
+        HttpTester.Response response = request("LOCK", "/context/file.txt", "Timeout: Second-60\r\n", lockInfo);
+        assertThat(response.getStatus(), is(HttpStatus.CREATED_201));
+        String lockToken = response.get("Lock-Token");
+        assertThat(lockToken, notNullValue());
+        assertThat(response.getContent(), containsString("mailto:jane@example.com"));
+        assertThat(Files.exists(docRoot.resolve("file.txt")), is(true));
+
+        // Conflicting lock.
+        response = request("LOCK", "/context/file.txt", null, lockInfo);
+        assertThat(response.getStatus(), is(HttpStatus.LOCKED_423));
+
+        // Modifications require the lock token.
+        response = request("PUT", "/context/file.txt", null, "hello");
+        assertThat(response.getStatus(), is(HttpStatus.LOCKED_423));
+        response = request("PUT", "/context/file.txt", "If: (" + lockToken + ")\r\n", "hello");
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+
+        // Refresh.
+        response = request("LOCK", "/context/file.txt", "If: (" + lockToken + ")\r\nTimeout: Second-120\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.OK_200));
+        assertThat(response.getContent(), containsString("<D:timeout>Second-1"));
+
+        response = request("PROPFIND", "/context/file.txt", "Depth: 0\r\n", null);
+        assertThat(response.getContent(), containsString("<D:lockdiscovery><D:activelock>"));
+
+        response = request("UNLOCK", "/context/file.txt", "Lock-Token: <opaquelocktoken:unknown>\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.CONFLICT_409));
+        response = request("UNLOCK", "/context/file.txt", "Lock-Token: " + lockToken + "\r\n", null);
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+
+        response = request("DELETE", "/context/file.txt", null, null);
+        assertThat(response.getStatus(), is(HttpStatus.NO_CONTENT_204));
+    }
+
+    @Test
+    public void testReadOnly() throws Exception
+    {
+        holder.setInitParameter("readOnly", "true");
+        Files.writeString(docRoot.resolve("file.txt"), "content");
+        server.start();
+
+        assertThat(request("PUT", "/context/file.txt", null, "hello").getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(request("DELETE", "/context/file.txt", null, null).getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(request("MKCOL", "/context/dir", null, null).getStatus(), is(HttpStatus.FORBIDDEN_403));
+        assertThat(request("PROPFIND", "/context/file.txt", "Depth: 0\r\n", null).getStatus(), is(HttpStatus.MULTI_STATUS_207));
+        assertThat(request("OPTIONS", "/context/", null, null).get(HttpHeader.ALLOW), not(containsString("PUT")));
+    }
+
+    @Test
+    public void testXMLExternalEntitiesRejected() throws Exception
+    {
+        Files.writeString(docRoot.resolve("file.txt"), "content");
+        server.start();
+
+        String propfind = "<?xml version=\"1.0\"?>" +
+            "<!DOCTYPE foo [<!ENTITY xxe SYSTEM \"file:///etc/passwd\">]>" +
+            "<D:propfind xmlns:D=\"DAV:\"><D:prop><D:displayname>&xxe;</D:displayname></D:prop></D:propfind>";
+        HttpTester.Response response = request("PROPFIND", "/context/file.txt", "Depth: 0\r\n", propfind);
+        assertThat(response.getStatus(), is(HttpStatus.BAD_REQUEST_400));
+    }
+}
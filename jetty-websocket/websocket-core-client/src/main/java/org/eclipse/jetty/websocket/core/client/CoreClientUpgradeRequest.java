@@ -258,7 +258,8 @@ public abstract class CoreClientUpgradeRequest extends HttpRequest implements Re
     {
         if (version == HttpVersion.HTTP_1_1)
             return new HttpUpgraderOverHTTP(this);
-        else if (version == HttpVersion.HTTP_2)
+        else if (version == HttpVersion.HTTP_2 || version == HttpVersion.HTTP_3)
+            // RFC 9220 uses the same extended CONNECT upgrade as RFC 8441.
             return new HttpUpgraderOverHTTP2(this);
         else
             throw new UnsupportedOperationException("Unsupported HTTP version for upgrade: " + version);
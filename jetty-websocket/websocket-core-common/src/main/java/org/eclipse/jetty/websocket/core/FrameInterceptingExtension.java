@@ -0,0 +1,226 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core;
+
+import java.util.Set;
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.websocket.core.internal.DemandingFlusher;
+import org.eclipse.jetty.websocket.core.internal.TransformingFlusher;
+
+/**
+ * <p>A base class for custom WebSocket extensions that intercept frames one at a time.</p>
+ * <p>Subclasses override {@link #interceptIncomingFrame(Frame, Callback)} and
+ * {@link #interceptOutgoingFrame(Frame, Callback, boolean)} to modify, replace or drop
+ * frames, while this class takes care of the demand: an incoming frame is only
+ * intercepted when there is demand for it from the upper layers, and the outgoing
+ * frames are intercepted one at a time, the next one only after the previous one
+ * has been forwarded.</p>
+ * <p>Negotiation:</p>
+ * <ul>
+ * <li>{@link #negotiate(ExtensionConfig)} is called with the offered configuration and
+ * returns the negotiated one, and may drop parameters or reject the offer
+ * by throwing {@link IllegalArgumentException}; by default, parameters not in
+ * {@link #getSupportedParameters()} are rejected;</li>
+ * <li>extensions that use an RSV bit must override the corresponding {@code isRsvXUser()}
+ * method; on the server, an offered extension that claims an RSV bit already claimed by a
+ * previous extension is not negotiated, while on the client the upgrade fails if the server
+ * negotiated such an extension;</li>
+ * <li>extensions are applied in the negotiated order: incoming frames traverse the
+ * extensions from the first to the last one before reaching the application, and outgoing
+ * frames traverse them from the last to the first one before reaching the network.
+ * On the server the negotiated order is the offered order, unless modified with
+ * {@code ServerUpgradeResponse.setExtensions(List)}.</li>
+ * </ul>
+ * <p>Extensions are registered by name with {@link WebSocketExtensionRegistry#register(String, Class)}
+ * and instantiated for each connection; configuration common to all the connections, such as keys,
+ * can be set with {@link WebSocketComponents#setExtensionConfiguration(String, Object)} and is
+ * retrieved with {@link #getExtensionConfiguration(Class)}.</p>
+ */
+public abstract class FrameInterceptingExtension extends AbstractExtension
+{
+    private final IncomingFlusher incomingFlusher = new IncomingFlusher();
+    private final OutgoingFlusher outgoingFlusher = new OutgoingFlusher();
+    private WebSocketComponents components;
+
+    @Override
+    public void init(ExtensionConfig config, WebSocketComponents components)
+    {
+        this.components = components;
+        super.init(negotiate(config), components);
+    }
+
+    /**
+     * <p>Negotiates the parameters of this extension.</p>
+     *
+     * @param offered the configuration offered by the client, on both the server and the client
+     * @return the negotiated configuration
+     * @throws IllegalArgumentException if the configuration is not acceptable
+     */
+    protected ExtensionConfig negotiate(ExtensionConfig offered)
+    {
+        Set<String> supported = getSupportedParameters();
+        if (supported != null)
+        {
+            for (String parameter : offered.getParameterKeys())
+            {
+                if (!parameter.startsWith("@") && !supported.contains(parameter))
+                    throw new IllegalArgumentException("Unsupported parameter " + parameter + " for extension " + offered.getName());
+            }
+        }
+        return offered;
+    }
+
+    /**
+     * @return the names of the parameters supported by this extension, or null to accept any parameter
+     */
+    protected Set<String> getSupportedParameters()
+    {
+        return null;
+    }
+
+    /**
+     * @param type the type of the configuration
+     * @param <T> the type of the configuration
+     * @return the configuration of this extension set in the {@link WebSocketComponents}, or null if there is none of the given type
+     * @see WebSocketComponents#setExtensionConfiguration(String, Object)
+     */
+    protected <T> T getExtensionConfiguration(Class<T> type)
+    {
+        Object configuration = components == null ? null : components.getExtensionConfiguration(getName());
+        return type.isInstance(configuration) ? type.cast(configuration) : null;
+    }
+
+    /**
+     * <p>Intercepts an incoming frame, including control frames.</p>
+     * <p>Before returning, implementations must either call {@link #forwardIncomingFrame(Frame, Callback)}
+     * exactly once, or complete the callback to drop the frame, in which case the next frame is
+     * intercepted.</p>
+     *
+     * @param frame the incoming frame
+     * @param callback the callback to complete when the frame payload is consumed
+     */
+    protected void interceptIncomingFrame(Frame frame, Callback callback)
+    {
+        forwardIncomingFrame(frame, callback);
+    }
+
+    /**
+     * @param frame the frame to forward towards the application
+     * @param callback the callback completed when the frame payload is consumed
+     */
+    protected void forwardIncomingFrame(Frame frame, Callback callback)
+    {
+        incomingFlusher.emitFrame(frame, callback);
+    }
+
+    /**
+     * <p>Intercepts an outgoing frame, including control frames.</p>
+     * <p>Implementations must either call {@link #forwardOutgoingFrame(Frame, Callback, boolean)}
+     * exactly once, possibly asynchronously, or complete the callback to drop the frame;
+     * the next outgoing frame is only intercepted when the callback is completed.
+     * Failing the callback fails this and all the subsequent outgoing frames.</p>
+     *
+     * @param frame the outgoing frame
+     * @param callback the callback to complete when the frame is sent
+     * @param batch whether the frame can be batched
+     */
+    protected void interceptOutgoingFrame(Frame frame, Callback callback, boolean batch)
+    {
+        forwardOutgoingFrame(frame, callback, batch);
+    }
+
+    /**
+     * @param frame the frame to forward towards the network
+     * @param callback the callback completed when the frame is sent
+     * @param batch whether the frame can be batched
+     */
+    protected void forwardOutgoingFrame(Frame frame, Callback callback, boolean batch)
+    {
+        nextOutgoingFrame(frame, callback, batch);
+    }
+
+    @Override
+    public void onFrame(Frame frame, Callback callback)
+    {
+        incomingFlusher.onFrame(frame, callback);
+    }
+
+    @Override
+    public void sendFrame(Frame frame, Callback callback, boolean batch)
+    {
+        outgoingFlusher.sendFrame(frame, callback, batch);
+    }
+
+    /**
+     * <p>Called by the extension stack when the upper layers demand frames.</p>
+     *
+     * @param n the number of frames demanded
+     */
+    public void demand(long n)
+    {
+        incomingFlusher.demand(n);
+    }
+
+    /**
+     * <p>Called by the extension stack to set where the demand is forwarded to the lower layers.</p>
+     *
+     * @param nextDemand the demand of the next extension towards the network
+     */
+    public void setNextDemand(LongConsumer nextDemand)
+    {
+        incomingFlusher.setNextDemand(nextDemand);
+    }
+
+    @Override
+    public void close()
+    {
+        incomingFlusher.closeFlusher();
+        outgoingFlusher.closeFlusher();
+    }
+
+    private class IncomingFlusher extends DemandingFlusher
+    {
+        private IncomingFlusher()
+        {
+            super(FrameInterceptingExtension.this::nextIncomingFrame);
+        }
+
+        @Override
+        protected boolean handle(Frame frame, Callback callback, boolean first)
+        {
+            interceptIncomingFrame(frame, callback);
+            return true;
+        }
+    }
+
+    private class OutgoingFlusher extends TransformingFlusher
+    {
+        @Override
+        protected boolean onFrame(Frame frame, Callback callback, boolean batch)
+        {
+            interceptOutgoingFrame(frame, callback, batch);
+            return true;
+        }
+
+        @Override
+        protected boolean transform(Callback callback)
+        {
+            // Each frame is intercepted in a single step.
+            callback.succeeded();
+            return true;
+        }
+    }
+}
@@ -0,0 +1,552 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core;
+
+import java.io.ByteArrayOutputStream;
+import java.net.SocketAddress;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.time.Duration;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.atomic.AtomicBoolean;
+import java.util.function.Consumer;
+
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.websocket.core.MessageInterceptor.Message;
+import org.eclipse.jetty.websocket.core.exception.MessageTooLargeException;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link FrameHandler} that applies a chain of {@link MessageInterceptor}s to the
+ * messages received and sent by the wrapped {@link FrameHandler}.</p>
+ * <p>Fragmented messages are aggregated, up to the max message size of the session, before
+ * being intercepted, and are then delivered to the wrapped handler, or sent to the remote
+ * peer, as a single frame; control frames are not intercepted.</p>
+ * <p>Server endpoints are wrapped when message interceptors are added to the
+ * {@code WebSocketMappings} of their upgrade mapping, while client endpoints may be
+ * wrapped explicitly before connecting.</p>
+ */
+public class InterceptingFrameHandler implements FrameHandler
+{
+    private static final Logger LOG = LoggerFactory.getLogger(InterceptingFrameHandler.class);
+
+    private final Aggregator inbound = new Aggregator();
+    private final Aggregator outbound = new Aggregator();
+    private final FrameHandler handler;
+    private final List<MessageInterceptor> interceptors;
+    private CoreSession coreSession;
+
+    public InterceptingFrameHandler(FrameHandler handler, List<MessageInterceptor> interceptors)
+    {
+        this.handler = handler;
+        this.interceptors = List.copyOf(interceptors);
+    }
+
+    public FrameHandler getFrameHandler()
+    {
+        return handler;
+    }
+
+    public List<MessageInterceptor> getMessageInterceptors()
+    {
+        return interceptors;
+    }
+
+    @Override
+    public void onOpen(CoreSession coreSession, Callback callback)
+    {
+        this.coreSession = coreSession;
+        handler.onOpen(new InterceptingCoreSession(coreSession), callback);
+    }
+
+    @Override
+    public void onFrame(Frame frame, Callback callback)
+    {
+        if (!frame.isDataFrame())
+        {
+            handler.onFrame(frame, callback);
+            return;
+        }
+
+        Message message;
+        try
+        {
+            message = inbound.aggregate(frame, frame.getOpCode() == OpCode.TEXT || inbound.opCode == OpCode.TEXT
+                ? coreSession.getMaxTextMessageSize()
+                : coreSession.getMaxBinaryMessageSize());
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+            return;
+        }
+
+        if (message == null)
+        {
+            // Wait for the next fragment.
+            callback.succeeded();
+            demand();
+            return;
+        }
+
+        new Step(true, 0, m -> handler.onFrame(new Frame(m.getOpCode(), true, m.getPayload()), callback), () ->
+        {
+            callback.succeeded();
+            demand();
+        }, callback::failed).next(message);
+    }
+
+    private void demand()
+    {
+        // The wrapped handler does not receive the frame, so it cannot demand.
+        if (isDemanding())
+            coreSession.demand(1);
+    }
+
+    @Override
+    public void onError(Throwable cause, Callback callback)
+    {
+        handler.onError(cause, callback);
+    }
+
+    @Override
+    public void onClosed(CloseStatus closeStatus, Callback callback)
+    {
+        handler.onClosed(closeStatus, callback);
+    }
+
+    @Override
+    public boolean isDemanding()
+    {
+        return handler.isDemanding();
+    }
+
+    private void send(Frame frame, Callback callback, boolean batch)
+    {
+        if (!frame.isDataFrame())
+        {
+            coreSession.sendFrame(frame, callback, batch);
+            return;
+        }
+
+        Message message;
+        try
+        {
+            message = outbound.aggregate(frame, -1);
+        }
+        catch (Throwable x)
+        {
+            callback.failed(x);
+            return;
+        }
+
+        if (message == null)
+        {
+            callback.succeeded();
+            return;
+        }
+
+        new Step(false, interceptors.size() - 1, m -> coreSession.sendFrame(new Frame(m.getOpCode(), true, m.getPayload()), callback, batch),
+            callback::succeeded, callback::failed).next(message);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), handler, interceptors);
+    }
+
+    /**
+     * <p>Aggregates the fragments of a message.</p>
+     */
+    private static class Aggregator
+    {
+        private ByteArrayOutputStream buffer;
+        private byte opCode = OpCode.UNDEFINED;
+
+        /**
+         * @return the complete message, or null if the frame is not the last fragment
+         */
+        private Message aggregate(Frame frame, long maxSize)
+        {
+            ByteBuffer payload = frame.hasPayload() ? frame.getPayload() : BufferUtil.EMPTY_BUFFER;
+            if (frame.getOpCode() != OpCode.CONTINUATION)
+            {
+                // Unfragmented messages are not copied.
+                if (frame.isFin())
+                    return new Message(frame.getOpCode(), payload);
+                opCode = frame.getOpCode();
+                buffer = new ByteArrayOutputStream();
+            }
+            else if (buffer == null)
+            {
+                throw new IllegalStateException("CONTINUATION frame without a message");
+            }
+
+            if (maxSize > 0 && buffer.size() + payload.remaining() > maxSize)
+            {
+                buffer = null;
+                opCode = OpCode.UNDEFINED;
+                throw new MessageTooLargeException("Message larger than " + maxSize + " bytes");
+            }
+            buffer.writeBytes(BufferUtil.toArray(payload));
+            if (!frame.isFin())
+                return null;
+
+            Message message = new Message(opCode, ByteBuffer.wrap(buffer.toByteArray()));
+            buffer = null;
+            opCode = OpCode.UNDEFINED;
+            return message;
+        }
+    }
+
+    /**
+     * <p>A step of the interceptor chain of a message, that can be completed only once.</p>
+     */
+    private class Step implements MessageInterceptor.Chain
+    {
+        private final AtomicBoolean completed = new AtomicBoolean();
+        private final boolean inbound;
+        private final int index;
+        private final Consumer<Message> onProceed;
+        private final Runnable onDrop;
+        private final Consumer<Throwable> onReject;
+
+        private Step(boolean inbound, int index, Consumer<Message> onProceed, Runnable onDrop, Consumer<Throwable> onReject)
+        {
+            this.inbound = inbound;
+            this.index = index;
+            this.onProceed = onProceed;
+            this.onDrop = onDrop;
+            this.onReject = onReject;
+        }
+
+        private void next(Message message)
+        {
+            if (index < 0 || index >= interceptors.size())
+            {
+                onProceed.accept(message);
+                return;
+            }
+
+            MessageInterceptor interceptor = interceptors.get(index);
+            try
+            {
+                if (inbound)
+                    interceptor.onInboundMessage(coreSession, message, this);
+                else
+                    interceptor.onOutboundMessage(coreSession, message, this);
+            }
+            catch (Throwable x)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("Failure intercepting {} with {}", message, interceptor, x);
+                reject(x);
+            }
+        }
+
+        private boolean complete()
+        {
+            if (completed.compareAndSet(false, true))
+                return true;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Chain already completed {}", interceptors.get(index));
+            return false;
+        }
+
+        @Override
+        public void proceed(Message message)
+        {
+            if (complete())
+                new Step(inbound, inbound ? index + 1 : index - 1, onProceed, onDrop, onReject).next(message);
+        }
+
+        @Override
+        public void drop()
+        {
+            if (complete())
+                onDrop.run();
+        }
+
+        @Override
+        public void reject(Throwable failure)
+        {
+            if (complete())
+                onReject.accept(failure);
+        }
+    }
+
+    /**
+     * <p>The session given to the wrapped handler, that intercepts the outbound messages.</p>
+     */
+    private class InterceptingCoreSession implements CoreSession
+    {
+        private final CoreSession delegate;
+
+        private InterceptingCoreSession(CoreSession delegate)
+        {
+            this.delegate = delegate;
+        }
+
+        @Override
+        public void sendFrame(Frame frame, Callback callback, boolean batch)
+        {
+            send(frame, callback, batch);
+        }
+
+        @Override
+        public String getNegotiatedSubProtocol()
+        {
+            return delegate.getNegotiatedSubProtocol();
+        }
+
+        @Override
+        public List<ExtensionConfig> getNegotiatedExtensions()
+        {
+            return delegate.getNegotiatedExtensions();
+        }
+
+        @Override
+        public Map<String, List<String>> getParameterMap()
+        {
+            return delegate.getParameterMap();
+        }
+
+        @Override
+        public String getProtocolVersion()
+        {
+            return delegate.getProtocolVersion();
+        }
+
+        @Override
+        public URI getRequestURI()
+        {
+            return delegate.getRequestURI();
+        }
+
+        @Override
+        public boolean isSecure()
+        {
+            return delegate.isSecure();
+        }
+
+        @Override
+        public Behavior getBehavior()
+        {
+            return delegate.getBehavior();
+        }
+
+        @Override
+        public WebSocketComponents getWebSocketComponents()
+        {
+            return delegate.getWebSocketComponents();
+        }
+
+        @Override
+        public ByteBufferPool getByteBufferPool()
+        {
+            return delegate.getByteBufferPool();
+        }
+
+        @Override
+        public SocketAddress getLocalAddress()
+        {
+            return delegate.getLocalAddress();
+        }
+
+        @Override
+        public SocketAddress getRemoteAddress()
+        {
+            return delegate.getRemoteAddress();
+        }
+
+        @Override
+        public boolean isInputOpen()
+        {
+            return delegate.isInputOpen();
+        }
+
+        @Override
+        public boolean isOutputOpen()
+        {
+            return delegate.isOutputOpen();
+        }
+
+        @Override
+        public void flush(Callback callback)
+        {
+            delegate.flush(callback);
+        }
+
+        @Override
+        public void close(Callback callback)
+        {
+            delegate.close(callback);
+        }
+
+        @Override
+        public void close(int statusCode, String reason, Callback callback)
+        {
+            delegate.close(statusCode, reason, callback);
+        }
+
+        @Override
+        public void abort()
+        {
+            delegate.abort();
+        }
+
+        @Override
+        public void demand(long n)
+        {
+            delegate.demand(n);
+        }
+
+        @Override
+        public boolean isRsv1Used()
+        {
+            return delegate.isRsv1Used();
+        }
+
+        @Override
+        public boolean isRsv2Used()
+        {
+            return delegate.isRsv2Used();
+        }
+
+        @Override
+        public boolean isRsv3Used()
+        {
+            return delegate.isRsv3Used();
+        }
+
+        @Override
+        public Duration getIdleTimeout()
+        {
+            return delegate.getIdleTimeout();
+        }
+
+        @Override
+        public Duration getWriteTimeout()
+        {
+            return delegate.getWriteTimeout();
+        }
+
+        @Override
+        public void setIdleTimeout(Duration timeout)
+        {
+            delegate.setIdleTimeout(timeout);
+        }
+
+        @Override
+        public void setWriteTimeout(Duration timeout)
+        {
+            delegate.setWriteTimeout(timeout);
+        }
+
+        @Override
+        public boolean isAutoFragment()
+        {
+            return delegate.isAutoFragment();
+        }
+
+        @Override
+        public void setAutoFragment(boolean autoFragment)
+        {
+            delegate.setAutoFragment(autoFragment);
+        }
+
+        @Override
+        public long getMaxFrameSize()
+        {
+            return delegate.getMaxFrameSize();
+        }
+
+        @Override
+        public void setMaxFrameSize(long maxFrameSize)
+        {
+            delegate.setMaxFrameSize(maxFrameSize);
+        }
+
+        @Override
+        public int getOutputBufferSize()
+        {
+            return delegate.getOutputBufferSize();
+        }
+
+        @Override
+        public void setOutputBufferSize(int outputBufferSize)
+        {
+            delegate.setOutputBufferSize(outputBufferSize);
+        }
+
+        @Override
+        public int getInputBufferSize()
+        {
+            return delegate.getInputBufferSize();
+        }
+
+        @Override
+        public void setInputBufferSize(int inputBufferSize)
+        {
+            delegate.setInputBufferSize(inputBufferSize);
+        }
+
+        @Override
+        public long getMaxBinaryMessageSize()
+        {
+            return delegate.getMaxBinaryMessageSize();
+        }
+
+        @Override
+        public void setMaxBinaryMessageSize(long maxSize)
+        {
+            delegate.setMaxBinaryMessageSize(maxSize);
+        }
+
+        @Override
+        public long getMaxTextMessageSize()
+        {
+            return delegate.getMaxTextMessageSize();
+        }
+
+        @Override
+        public void setMaxTextMessageSize(long maxSize)
+        {
+            delegate.setMaxTextMessageSize(maxSize);
+        }
+
+        @Override
+        public int getMaxOutgoingFrames()
+        {
+            return delegate.getMaxOutgoingFrames();
+        }
+
+        @Override
+        public void setMaxOutgoingFrames(int maxOutgoingFrames)
+        {
+            delegate.setMaxOutgoingFrames(maxOutgoingFrames);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s]", getClass().getSimpleName(), hashCode(), delegate);
+        }
+    }
+}
@@ -0,0 +1,150 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.Objects;
+
+import org.eclipse.jetty.util.BufferUtil;
+
+/**
+ * <p>Intercepts the complete WebSocket messages exchanged by an endpoint, before the
+ * inbound messages reach the application and before the outbound messages are sent
+ * to the remote peer.</p>
+ * <p>Interceptors are applied by {@link InterceptingFrameHandler}, in order for inbound
+ * messages, and in reverse order for outbound messages, so that the first interceptor
+ * is the closest to the network.
+ * An interceptor may observe a message, replace it with a modified message, drop it
+ * or reject it, possibly asynchronously, by calling exactly one of the {@link Chain}
+ * methods; until then, no other message is delivered in the same direction.</p>
+ * <p>Typical uses are schema validation, redaction of sensitive data and audit logging.</p>
+ */
+public interface MessageInterceptor
+{
+    /**
+     * <p>Intercepts a message received from the remote peer.</p>
+     * <p>Rejecting an inbound message with a {@link org.eclipse.jetty.websocket.core.exception.CloseException}
+     * closes the session with the exception status code, for example {@link CloseStatus#POLICY_VIOLATION}.</p>
+     *
+     * @param coreSession the session
+     * @param message the inbound message, whose payload is only valid until the chain is completed
+     * @param chain the chain to complete
+     */
+    default void onInboundMessage(CoreSession coreSession, Message message, Chain chain)
+    {
+        chain.proceed(message);
+    }
+
+    /**
+     * <p>Intercepts a message sent by the application.</p>
+     * <p>Rejecting an outbound message fails the callback of the send operation.</p>
+     *
+     * @param coreSession the session
+     * @param message the outbound message, whose payload is only valid until the chain is completed
+     * @param chain the chain to complete
+     */
+    default void onOutboundMessage(CoreSession coreSession, Message message, Chain chain)
+    {
+        chain.proceed(message);
+    }
+
+    /**
+     * <p>The continuation of the interception of a message.</p>
+     */
+    interface Chain
+    {
+        /**
+         * <p>Passes the given message, either the original message or a replacement,
+         * to the next interceptor, or to the application or the network.</p>
+         *
+         * @param message the message
+         */
+        void proceed(Message message);
+
+        /**
+         * <p>Silently discards the message.</p>
+         */
+        void drop();
+
+        /**
+         * <p>Rejects the message.</p>
+         *
+         * @param failure the reason of the rejection
+         */
+        void reject(Throwable failure);
+    }
+
+    /**
+     * <p>A complete TEXT or BINARY WebSocket message.</p>
+     */
+    class Message
+    {
+        private final byte opCode;
+        private final ByteBuffer payload;
+
+        public Message(byte opCode, ByteBuffer payload)
+        {
+            if (opCode != OpCode.TEXT && opCode != OpCode.BINARY)
+                throw new IllegalArgumentException("Not a message opcode " + OpCode.name(opCode));
+            this.opCode = opCode;
+            this.payload = Objects.requireNonNull(payload);
+        }
+
+        public static Message text(String text)
+        {
+            return new Message(OpCode.TEXT, BufferUtil.toBuffer(text, StandardCharsets.UTF_8));
+        }
+
+        public static Message binary(ByteBuffer payload)
+        {
+            return new Message(OpCode.BINARY, payload);
+        }
+
+        /**
+         * @return {@link OpCode#TEXT} or {@link OpCode#BINARY}
+         */
+        public byte getOpCode()
+        {
+            return opCode;
+        }
+
+        public boolean isText()
+        {
+            return opCode == OpCode.TEXT;
+        }
+
+        /**
+         * @return a view of the message payload, whose position and limit can be modified
+         */
+        public ByteBuffer getPayload()
+        {
+            return payload.slice();
+        }
+
+        /**
+         * @return the payload decoded as UTF-8
+         */
+        public String getText()
+        {
+            return BufferUtil.toString(payload, StandardCharsets.UTF_8);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,len=%d]", getClass().getSimpleName(), hashCode(), OpCode.name(opCode), payload.remaining());
+        }
+    }
+}
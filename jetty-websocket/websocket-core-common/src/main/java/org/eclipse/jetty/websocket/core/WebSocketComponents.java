@@ -13,12 +13,17 @@
 
 package org.eclipse.jetty.websocket.core;
 
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
 import java.util.concurrent.Executor;
+import java.util.concurrent.atomic.AtomicInteger;
 import java.util.zip.Deflater;
 
 import org.eclipse.jetty.io.ByteBufferPool;
 import org.eclipse.jetty.io.MappedByteBufferPool;
 import org.eclipse.jetty.util.DecoratedObjectFactory;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.component.ContainerLifeCycle;
 import org.eclipse.jetty.util.compression.CompressionPool;
 import org.eclipse.jetty.util.compression.DeflaterPool;
@@ -29,6 +34,7 @@ import org.eclipse.jetty.util.thread.QueuedThreadPool;
  * A collection of components which are the resources needed for websockets such as
  * {@link ByteBufferPool}, {@link WebSocketExtensionRegistry}, and {@link DecoratedObjectFactory}.
  */
+@ManagedObject("WebSocket Components")
 public class WebSocketComponents extends ContainerLifeCycle
 {
     private final DecoratedObjectFactory _objectFactory;
@@ -37,6 +43,9 @@ public class WebSocketComponents extends ContainerLifeCycle
     private final ByteBufferPool _bufferPool;
     private final InflaterPool _inflaterPool;
     private final DeflaterPool _deflaterPool;
+    private final AtomicInteger _deflaterContexts = new AtomicInteger();
+    private final Map<String, Object> _extensionConfigurations = new ConcurrentHashMap<>();
+    private int _maxDeflaterContexts = -1;
 
     public WebSocketComponents()
     {
@@ -106,4 +115,91 @@ public class WebSocketComponents extends ContainerLifeCycle
     {
         return _deflaterPool;
     }
+
+    /**
+     * <p>Sets the configuration of the extension with the given name, shared by all
+     * the instances of the extension created with these components.</p>
+     *
+     * @param extensionName the name of the extension
+     * @param configuration the configuration of the extension, or null to remove it
+     * @see FrameInterceptingExtension#getExtensionConfiguration(Class)
+     */
+    public void setExtensionConfiguration(String extensionName, Object configuration)
+    {
+        if (configuration == null)
+            _extensionConfigurations.remove(extensionName);
+        else
+            _extensionConfigurations.put(extensionName, configuration);
+    }
+
+    /**
+     * @param extensionName the name of the extension
+     * @return the configuration of the extension with the given name, or null if none was set
+     */
+    public Object getExtensionConfiguration(String extensionName)
+    {
+        return _extensionConfigurations.get(extensionName);
+    }
+
+    /**
+     * @return the max number of compression contexts that can be held at the same time
+     * by all the sessions using these components, or -1 for no limit
+     */
+    @ManagedAttribute("The max number of compression contexts held at the same time, or -1 for no limit")
+    public int getMaxDeflaterContexts()
+    {
+        return _maxDeflaterContexts;
+    }
+
+    /**
+     * <p>Sets the max number of compression contexts that can be held at the same time
+     * by all the sessions using these components.</p>
+     * <p>Each compression context uses a significant amount of native memory, so this
+     * value effectively caps the memory used to compress outgoing messages;
+     * when the limit is reached, outgoing messages are sent uncompressed until
+     * a compression context is released.</p>
+     *
+     * @param maxDeflaterContexts the max number of compression contexts, or -1 for no limit
+     */
+    public void setMaxDeflaterContexts(int maxDeflaterContexts)
+    {
+        _maxDeflaterContexts = maxDeflaterContexts;
+    }
+
+    /**
+     * @return the number of compression contexts currently held
+     */
+    @ManagedAttribute("The number of compression contexts currently held")
+    public int getDeflaterContexts()
+    {
+        return _deflaterContexts.get();
+    }
+
+    /**
+     * <p>Attempts to reserve a compression context within the
+     * {@link #setMaxDeflaterContexts(int) max number of compression contexts}.</p>
+     *
+     * @return whether the compression context has been reserved,
+     * in which case {@link #releaseDeflaterContext()} must be called when the context is released
+     */
+    public boolean tryAcquireDeflaterContext()
+    {
+        while (true)
+        {
+            int contexts = _deflaterContexts.get();
+            int max = _maxDeflaterContexts;
+            if (max >= 0 && contexts >= max)
+                return false;
+            if (_deflaterContexts.compareAndSet(contexts, contexts + 1))
+                return true;
+        }
+    }
+
+    /**
+     * <p>Releases a compression context reserved with {@link #tryAcquireDeflaterContext()}.</p>
+     */
+    public void releaseDeflaterContext()
+    {
+        _deflaterContexts.decrementAndGet();
+    }
 }
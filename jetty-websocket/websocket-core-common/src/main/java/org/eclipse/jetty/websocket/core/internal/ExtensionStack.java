@@ -30,6 +30,7 @@ import org.eclipse.jetty.websocket.core.Behavior;
 import org.eclipse.jetty.websocket.core.Extension;
 import org.eclipse.jetty.websocket.core.ExtensionConfig;
 import org.eclipse.jetty.websocket.core.Frame;
+import org.eclipse.jetty.websocket.core.FrameInterceptingExtension;
 import org.eclipse.jetty.websocket.core.IncomingFrames;
 import org.eclipse.jetty.websocket.core.OutgoingFrames;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
@@ -184,22 +185,22 @@ public class ExtensionStack implements IncomingFrames, OutgoingFrames, Dumpable
             }
 
             // Check RSV
+            int rsvConflict = -1;
             if (ext.isRsv1User() && (rsvClaims[0] != null))
+                rsvConflict = 0;
+            else if (ext.isRsv2User() && (rsvClaims[1] != null))
+                rsvConflict = 1;
+            else if (ext.isRsv3User() && (rsvClaims[2] != null))
+                rsvConflict = 2;
+            if (rsvConflict >= 0)
             {
+                // The server can decline the offer, but the client cannot accept
+                // a response that negotiated conflicting extensions.
+                if (behavior == Behavior.CLIENT)
+                    throw new WebSocketException(String.format("negotiated extension %s conflicts with %s on RSV%d", config, rsvClaims[rsvConflict].getConfig(), rsvConflict + 1));
                 if (LOG.isDebugEnabled())
-                    LOG.debug("Not adding extension {}. Extension {} already claimed RSV1", config, rsvClaims[0]);
-                continue;
-            }
-            if (ext.isRsv2User() && (rsvClaims[1] != null))
-            {
-                if (LOG.isDebugEnabled())
-                    LOG.debug("Not adding extension {}. Extension {} already claimed RSV2", config, rsvClaims[1]);
-                continue;
-            }
-            if (ext.isRsv3User() && (rsvClaims[2] != null))
-            {
-                if (LOG.isDebugEnabled())
-                    LOG.debug("Not adding extension {}. Extension {} already claimed RSV3", config, rsvClaims[2]);
+                    LOG.debug("Not adding extension {}. Extension {} already claimed RSV{}", config, rsvClaims[rsvConflict], rsvConflict + 1);
+                ext.close();
                 continue;
             }
 
@@ -230,9 +231,13 @@ public class ExtensionStack implements IncomingFrames, OutgoingFrames, Dumpable
                 ext.setNextOutgoingFrames(outgoing);
                 outgoing = ext;
 
+                DemandChain demandingExtension = null;
                 if (ext instanceof DemandChain)
+                    demandingExtension = (DemandChain)ext;
+                else if (ext instanceof FrameInterceptingExtension)
+                    demandingExtension = new FrameInterceptingDemandChain((FrameInterceptingExtension)ext);
+                if (demandingExtension != null)
                 {
-                    DemandChain demandingExtension = (DemandChain)ext;
                     demandingExtension.setNextDemand(demandChain::demand);
                     demandChain = demandingExtension;
                 }
@@ -0,0 +1,44 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core.internal;
+
+import java.util.function.LongConsumer;
+
+import org.eclipse.jetty.websocket.core.FrameInterceptingExtension;
+
+/**
+ * Adapts a {@link FrameInterceptingExtension} to the {@link DemandChain} of the {@link ExtensionStack},
+ * so that the demand wiring does not leak into the public extension API.
+ */
+class FrameInterceptingDemandChain implements DemandChain
+{
+    private final FrameInterceptingExtension extension;
+
+    FrameInterceptingDemandChain(FrameInterceptingExtension extension)
+    {
+        this.extension = extension;
+    }
+
+    @Override
+    public void demand(long n)
+    {
+        extension.demand(n);
+    }
+
+    @Override
+    public void setNextDemand(LongConsumer nextDemand)
+    {
+        extension.setNextDemand(nextDemand);
+    }
+}
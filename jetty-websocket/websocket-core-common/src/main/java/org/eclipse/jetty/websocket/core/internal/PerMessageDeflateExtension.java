@@ -17,6 +17,7 @@ import java.nio.ByteBuffer;
 import java.util.HashMap;
 import java.util.Map;
 import java.util.concurrent.atomic.AtomicReference;
+import java.util.concurrent.atomic.LongAdder;
 import java.util.function.LongConsumer;
 import java.util.zip.DataFormatException;
 import java.util.zip.Deflater;
@@ -24,6 +25,8 @@ import java.util.zip.Inflater;
 
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.compression.DeflaterPool;
 import org.eclipse.jetty.util.compression.InflaterPool;
 import org.eclipse.jetty.websocket.core.AbstractExtension;
@@ -41,19 +44,37 @@ import org.slf4j.LoggerFactory;
  * Per Message Deflate Compression extension for WebSocket.
  * <p>
  * Attempts to follow <a href="https://tools.ietf.org/html/rfc7692">Compression Extensions for WebSocket</a>
+ * <p>
+ * The compressor and decompressor always use the max LZ77 window size of 15 bits, so the
+ * {@code client_max_window_bits} parameter is always accepted, while the {@code server_max_window_bits}
+ * parameter is only negotiated if it does not restrict the window size.
+ * <p>
+ * Outgoing messages are sent uncompressed when the
+ * {@link WebSocketComponents#setMaxDeflaterContexts(int) max number of compression contexts}
+ * is reached; the compression ratio of each direction is exposed via JMX.
  */
+@ManagedObject("Per Message Deflate Extension")
 public class PerMessageDeflateExtension extends AbstractExtension implements DemandChain
 {
     private static final byte[] TAIL_BYTES = new byte[]{0x00, 0x00, (byte)0xFF, (byte)0xFF};
     private static final ByteBuffer TAIL_BYTES_BUF = ByteBuffer.wrap(TAIL_BYTES);
     private static final Logger LOG = LoggerFactory.getLogger(PerMessageDeflateExtension.class);
     private static final int DEFAULT_BUF_SIZE = 8 * 1024;
+    private static final int MAX_WINDOW_BITS = 15;
 
     private final OutgoingFlusher outgoingFlusher;
     private final IncomingFlusher incomingFlusher;
     private DeflaterPool.Entry deflaterHolder;
     private InflaterPool.Entry inflaterHolder;
     private boolean incomingCompressed;
+    private boolean outgoingCompressed;
+    private boolean deflaterContextAcquired;
+    private WebSocketComponents components;
+    private final LongAdder bytesDeflatedIn = new LongAdder();
+    private final LongAdder bytesDeflatedOut = new LongAdder();
+    private final LongAdder bytesInflatedIn = new LongAdder();
+    private final LongAdder bytesInflatedOut = new LongAdder();
+    private final LongAdder messagesUncompressed = new LongAdder();
 
     private ExtensionConfig configRequested;
     private ExtensionConfig configNegotiated;
@@ -96,6 +117,7 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
     @Override
     public void init(final ExtensionConfig config, WebSocketComponents components)
     {
+        this.components = components;
         configRequested = new ExtensionConfig(config);
         Map<String, String> paramsNegotiated = new HashMap<>();
 
@@ -105,10 +127,21 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
             switch (key)
             {
                 case "client_max_window_bits":
+                {
+                    // The Inflater always uses the max window size, so it can
+                    // decompress data compressed with any window size.
+                    // Don't negotiate this parameter.
+                    break;
+                }
                 case "server_max_window_bits":
                 {
-                    // Not supported by Jetty
-                    // Don't negotiate these parameters
+                    // The Deflater always uses the max window size,
+                    // so only negotiate this parameter if it is the max.
+                    int windowBits = config.getParameter(key, MAX_WINDOW_BITS);
+                    if (windowBits == MAX_WINDOW_BITS)
+                        paramsNegotiated.put("server_max_window_bits", String.valueOf(MAX_WINDOW_BITS));
+                    else if (LOG.isDebugEnabled())
+                        LOG.debug("Unsupported server_max_window_bits={}", windowBits);
                     break;
                 }
                 case "client_no_context_takeover":
@@ -214,6 +247,77 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
             deflaterHolder.release();
             deflaterHolder = null;
         }
+        if (deflaterContextAcquired)
+        {
+            deflaterContextAcquired = false;
+            components.releaseDeflaterContext();
+        }
+    }
+
+    /**
+     * @return whether a Deflater is available to compress the next outgoing message
+     */
+    private boolean acquireDeflater()
+    {
+        if (deflaterHolder != null)
+            return true;
+        if (!components.tryAcquireDeflaterContext())
+            return false;
+        deflaterContextAcquired = true;
+        deflaterHolder = getDeflaterPool().acquire();
+        return true;
+    }
+
+    @ManagedAttribute("The number of uncompressed bytes of the outgoing messages")
+    public long getBytesDeflatedIn()
+    {
+        return bytesDeflatedIn.sum();
+    }
+
+    @ManagedAttribute("The number of compressed bytes of the outgoing messages")
+    public long getBytesDeflatedOut()
+    {
+        return bytesDeflatedOut.sum();
+    }
+
+    @ManagedAttribute("The number of compressed bytes of the incoming messages")
+    public long getBytesInflatedIn()
+    {
+        return bytesInflatedIn.sum();
+    }
+
+    @ManagedAttribute("The number of uncompressed bytes of the incoming messages")
+    public long getBytesInflatedOut()
+    {
+        return bytesInflatedOut.sum();
+    }
+
+    @ManagedAttribute("The number of outgoing messages sent uncompressed because no compression context was available")
+    public long getMessagesUncompressed()
+    {
+        return messagesUncompressed.sum();
+    }
+
+    /**
+     * @return the ratio between the compressed and the uncompressed size of the outgoing messages,
+     * or 0 if no messages have been compressed
+     */
+    @ManagedAttribute("The compression ratio of the outgoing messages")
+    public double getOutgoingCompressionRatio()
+    {
+        long in = getBytesDeflatedIn();
+        return in == 0 ? 0 : (double)getBytesDeflatedOut() / in;
+    }
+
+    /**
+     * @return the ratio between the compressed and the uncompressed size of the incoming messages,
+     * or 0 if no messages have been decompressed
+     */
+    @ManagedAttribute("The compression ratio of the incoming messages")
+    public double getIncomingCompressionRatio()
+    {
+        long out = getBytesInflatedOut();
+        return out == 0 ? 0 : (double)getBytesInflatedIn() / out;
     }
 
     @Override
@@ -274,9 +378,25 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
                 return true;
             }
 
+            if (frame.getOpCode() != OpCode.CONTINUATION)
+            {
+                outgoingCompressed = acquireDeflater();
+                if (!outgoingCompressed)
+                    messagesUncompressed.increment();
+            }
+
+            if (!outgoingCompressed)
+            {
+                if (LOG.isDebugEnabled())
+                    LOG.debug("No compression context available, sending uncompressed {}", frame);
+                nextOutgoingFrame(frame, callback, batch);
+                return true;
+            }
+
             _first = true;
             _frame = frame;
             _batch = batch;
+            bytesDeflatedIn.add(frame.getPayloadLength());
 
             // Provide the frames payload as input to the Deflater.
             getDeflater().setInput(frame.getPayload().slice());
@@ -352,6 +472,7 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
             chunk.setRsv1(_first && _frame.getOpCode() != OpCode.CONTINUATION);
             chunk.setPayload(payload);
             chunk.setFin(_frame.isFin() && finished);
+            bytesDeflatedOut.add(payload.remaining());
 
             nextOutgoingFrame(chunk, callback, _batch);
             return finished;
@@ -405,6 +526,7 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
 
                 // Provide the frames payload as input to the Inflater.
                 _tailBytes = false;
+                bytesInflatedIn.add(frame.getPayloadLength());
                 getInflater().setInput(frame.getPayload().slice());
             }
 
@@ -459,6 +581,7 @@ public class PerMessageDeflateExtension extends AbstractExtension implements Dem
                 }
             }
 
+            bytesInflatedOut.add(payload.remaining());
             Frame chunk = new Frame(first ? frame.getOpCode() : OpCode.CONTINUATION);
             chunk.setRsv1(false);
             chunk.setPayload(payload);
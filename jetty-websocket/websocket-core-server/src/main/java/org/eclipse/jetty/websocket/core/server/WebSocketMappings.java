@@ -14,6 +14,10 @@
 package org.eclipse.jetty.websocket.core.server;
 
 import java.io.IOException;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.function.Consumer;
 import javax.servlet.ServletContext;
 import javax.servlet.http.HttpServletRequest;
@@ -33,6 +37,8 @@ import org.eclipse.jetty.util.component.LifeCycle;
 import org.eclipse.jetty.websocket.core.Configuration;
 import org.eclipse.jetty.websocket.core.CoreSession;
 import org.eclipse.jetty.websocket.core.FrameHandler;
+import org.eclipse.jetty.websocket.core.InterceptingFrameHandler;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
 import org.eclipse.jetty.websocket.core.exception.WebSocketException;
 import org.eclipse.jetty.websocket.core.server.internal.CreatorNegotiator;
@@ -49,6 +55,8 @@ import org.slf4j.LoggerFactory;
  * creator is used to create a POJO for the WebSocket endpoint, the factory is used to
  * wrap that POJO with a {@link FrameHandler} and the customizer is used to configure the resulting
  * {@link CoreSession}.</p>
+ * <p>{@link MessageInterceptor}s may be added to a mapping, in which case the {@link FrameHandler}
+ * is wrapped with an {@link InterceptingFrameHandler}.</p>
  */
 public class WebSocketMappings implements Dumpable, LifeCycle.Listener
 {
@@ -112,6 +120,7 @@ public class WebSocketMappings implements Dumpable, LifeCycle.Listener
     }
 
     private final PathMappings<WebSocketNegotiator> mappings = new PathMappings<>();
+    private final Map<PathSpec, List<MessageInterceptor>> interceptors = new ConcurrentHashMap<>();
     private final WebSocketComponents components;
     private final Handshaker handshaker = new HandshakerSelector();
 
@@ -139,6 +148,7 @@ public class WebSocketMappings implements Dumpable, LifeCycle.Listener
         {
             contextHandler.removeBean(mapping);
             mappings.reset();
+            interceptors.clear();
         }
     }
 
@@ -197,9 +207,32 @@ public class WebSocketMappings implements Dumpable, LifeCycle.Listener
 
     public boolean removeMapping(PathSpec pathSpec)
     {
+        interceptors.remove(pathSpec);
         return mappings.remove(pathSpec);
     }
 
+    /**
+     * <p>Adds a {@link MessageInterceptor} to the messages of the WebSocket sessions
+     * upgraded by the given mapping, after the interceptors already added.</p>
+     *
+     * @param pathSpec the pathspec of the mapping
+     * @param interceptor the interceptor to add
+     */
+    public void addMessageInterceptor(PathSpec pathSpec, MessageInterceptor interceptor)
+    {
+        interceptors.computeIfAbsent(pathSpec, key -> new CopyOnWriteArrayList<>()).add(interceptor);
+    }
+
+    /**
+     * @param pathSpec the pathspec of the mapping
+     * @return the message interceptors of the given mapping
+     */
+    public List<MessageInterceptor> getMessageInterceptors(PathSpec pathSpec)
+    {
+        List<MessageInterceptor> list = interceptors.get(pathSpec);
+        return list == null ? List.of() : List.copyOf(list);
+    }
+
     /**
      * Get the matching {@link MappedResource} for the provided target.
      *
@@ -224,20 +257,54 @@ public class WebSocketMappings implements Dumpable, LifeCycle.Listener
         // parameters before attempting to match a specific mapping.
         String target = URIUtil.addPaths(request.getServletPath(), request.getPathInfo());
 
-        WebSocketNegotiator negotiator = getMatchedNegotiator(target, pathSpec ->
-        {
-            // Store PathSpec resource mapping as request attribute,
-            // for WebSocketCreator implementors to use later if they wish.
-            request.setAttribute(PathSpec.class.getName(), pathSpec);
-        });
-
-        if (negotiator == null)
+        MatchedResource<WebSocketNegotiator> mapping = this.mappings.getMatched(target);
+        if (mapping == null)
             return false;
 
+        // Store PathSpec resource mapping as request attribute,
+        // for WebSocketCreator implementors to use later if they wish.
+        request.setAttribute(PathSpec.class.getName(), mapping.getPathSpec());
+        WebSocketNegotiator negotiator = mapping.getResource();
+
+        List<MessageInterceptor> messageInterceptors = getMessageInterceptors(mapping.getPathSpec());
+        if (!messageInterceptors.isEmpty())
+            negotiator = new InterceptingNegotiator(negotiator, messageInterceptors);
+
         if (LOG.isDebugEnabled())
             LOG.debug("WebSocket Negotiated detected on {} for endpoint {}", target, negotiator);
 
         // We have an upgrade request
         return handshaker.upgradeRequest(negotiator, request, response, components, defaultCustomizer);
     }
+
+    private static class InterceptingNegotiator implements WebSocketNegotiator
+    {
+        private final WebSocketNegotiator negotiator;
+        private final List<MessageInterceptor> interceptors;
+
+        private InterceptingNegotiator(WebSocketNegotiator negotiator, List<MessageInterceptor> interceptors)
+        {
+            this.negotiator = negotiator;
+            this.interceptors = interceptors;
+        }
+
+        @Override
+        public FrameHandler negotiate(WebSocketNegotiation negotiation) throws IOException
+        {
+            FrameHandler handler = negotiator.negotiate(negotiation);
+            return handler == null ? null : new InterceptingFrameHandler(handler, interceptors);
+        }
+
+        @Override
+        public void customize(Configuration configurable)
+        {
+            negotiator.customize(configurable);
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), negotiator, interceptors);
+        }
+    }
 }
@@ -23,6 +23,7 @@ import org.eclipse.jetty.http.pathmap.ServletPathSpec;
 import org.eclipse.jetty.server.Request;
 import org.eclipse.jetty.server.handler.HandlerWrapper;
 import org.eclipse.jetty.websocket.core.Configuration;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
 
 public class WebSocketUpgradeHandler extends HandlerWrapper
@@ -50,6 +51,16 @@ public class WebSocketUpgradeHandler extends HandlerWrapper
         mappings.addMapping(pathSpec, negotiator);
     }
 
+    public void addMessageInterceptor(String pathSpec, MessageInterceptor interceptor)
+    {
+        mappings.addMessageInterceptor(new ServletPathSpec(pathSpec), interceptor);
+    }
+
+    public void addMessageInterceptor(PathSpec pathSpec, MessageInterceptor interceptor)
+    {
+        mappings.addMessageInterceptor(pathSpec, interceptor);
+    }
+
     public Configuration getConfiguration()
     {
         return customizer;
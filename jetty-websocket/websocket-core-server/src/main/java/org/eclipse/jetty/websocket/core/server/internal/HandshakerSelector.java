@@ -25,7 +25,7 @@ import org.eclipse.jetty.websocket.core.server.WebSocketNegotiator;
 /**
  * Selects between the two Handshaker implementations,
  * RFC6455 (HTTP/1.1 WebSocket Upgrades)
- * and RFC68441 (HTTP/2 WebSocket Upgrades)
+ * and RFC68441 (HTTP/2 WebSocket Upgrades, extended to HTTP/3 by RFC9220)
  */
 public class HandshakerSelector implements Handshaker
 {
@@ -44,10 +44,12 @@ public class RFC8441Handshaker extends AbstractHandshaker
             return false;
         }
 
-        if (!HttpVersion.HTTP_2.is(request.getProtocol()))
+        // RFC 9220 extends the RFC 8441 extended CONNECT method to HTTP/3.
+        String protocol = request.getProtocol();
+        if (!HttpVersion.HTTP_2.is(protocol) && !HttpVersion.HTTP_3.is(protocol))
         {
             if (LOG.isDebugEnabled())
-                LOG.debug("not upgraded HttpVersion!=2 {}", request);
+                LOG.debug("not upgraded HttpVersion!=2 and HttpVersion!=3 {}", request);
             return false;
         }
 
@@ -0,0 +1,188 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core;
+
+import java.net.URI;
+import java.util.List;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.util.BlockingArrayQueue;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.websocket.core.client.WebSocketCoreClient;
+import org.eclipse.jetty.websocket.core.exception.CloseException;
+import org.eclipse.jetty.websocket.core.server.WebSocketUpgradeHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class MessageInterceptorTest
+{
+    private final List<String> events = new CopyOnWriteArrayList<>();
+    private Server server;
+    private WebSocketUpgradeHandler upgradeHandler;
+    private WebSocketCoreClient client;
+    private URI serverUri;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        server = new Server();
+        ServerConnector connector = new ServerConnector(server);
+        server.addConnector(connector);
+
+        upgradeHandler = new WebSocketUpgradeHandler();
+        upgradeHandler.addMapping("/echo", new TestWebSocketNegotiator(new EchoFrameHandler()));
+        server.setHandler(upgradeHandler);
+        server.start();
+
+        client = new WebSocketCoreClient();
+        client.start();
+
+        serverUri = URI.create("ws://localhost:" + connector.getLocalPort());
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        client.stop();
+        server.stop();
+    }
+
+    private TestMessageHandler connect() throws Exception
+    {
+        TestMessageHandler clientEndpoint = new TestMessageHandler();
+        client.connect(clientEndpoint, serverUri.resolve("/echo")).get(5, TimeUnit.SECONDS);
+        assertTrue(clientEndpoint.openLatch.await(5, TimeUnit.SECONDS));
+        return clientEndpoint;
+    }
+
+    @Test
+    public void testModifyInboundAndOutbound() throws Exception
+    {
+        upgradeHandler.addMessageInterceptor("/echo", new Recorder("first"));
+        upgradeHandler.addMessageInterceptor("/echo", new MessageInterceptor()
+        {
+            @Override
+            public void onInboundMessage(CoreSession coreSession, Message message, Chain chain)
+            {
+                events.add("redact " + message.getText());
+                chain.proceed(Message.text(message.getText().replace("secret", "***")));
+            }
+
+            @Override
+            public void onOutboundMessage(CoreSession coreSession, Message message, Chain chain)
+            {
+                events.add("upper " + message.getText());
+                chain.proceed(Message.text(message.getText().toUpperCase()));
+            }
+        });
+
+        TestMessageHandler clientEndpoint = connect();
+        clientEndpoint.sendText("my secret", Callback.NOOP, false);
+
+        assertThat(clientEndpoint.textMessages.poll(5, TimeUnit.SECONDS), is("MY ***"));
+        // Inbound messages traverse the interceptors in order, outbound messages in reverse order.
+        assertThat(events, is(List.of("first in my secret", "redact my secret", "upper my ***", "first out MY ***")));
+    }
+
+    @Test
+    public void testFragmentedMessage() throws Exception
+    {
+        upgradeHandler.addMessageInterceptor("/echo", new Recorder("recorder"));
+
+        TestMessageHandler clientEndpoint = connect();
+        CoreSession coreSession = clientEndpoint.getCoreSession();
+        coreSession.sendFrame(new Frame(OpCode.TEXT, false, "hello "), Callback.NOOP, false);
+        coreSession.sendFrame(new Frame(OpCode.CONTINUATION, false, "fragmented "), Callback.NOOP, false);
+        coreSession.sendFrame(new Frame(OpCode.CONTINUATION, true, "world"), Callback.NOOP, false);
+
+        assertThat(clientEndpoint.textMessages.poll(5, TimeUnit.SECONDS), is("hello fragmented world"));
+        assertThat(events, is(List.of("recorder in hello fragmented world", "recorder out hello fragmented world")));
+    }
+
+    @Test
+    public void testAsyncDropAndReject() throws Exception
+    {
+        BlockingQueue<Runnable> pending = new BlockingArrayQueue<>();
+        upgradeHandler.addMessageInterceptor("/echo", new MessageInterceptor()
+        {
+            @Override
+            public void onInboundMessage(CoreSession coreSession, Message message, Chain chain)
+            {
+                switch (message.getText())
+                {
+                    case "drop":
+                        chain.drop();
+                        break;
+                    case "reject":
+                        chain.reject(new CloseException(CloseStatus.POLICY_VIOLATION, "rejected"));
+                        break;
+                    default:
+                        // Complete the chain later, from another thread.
+                        pending.offer(() -> chain.proceed(message));
+                        break;
+                }
+            }
+        });
+
+        TestMessageHandler clientEndpoint = connect();
+        clientEndpoint.sendText("async", Callback.NOOP, false);
+        Runnable proceed = pending.poll(5, TimeUnit.SECONDS);
+        new Thread(proceed).start();
+        assertThat(clientEndpoint.textMessages.poll(5, TimeUnit.SECONDS), is("async"));
+
+        clientEndpoint.sendText("drop", Callback.NOOP, false);
+        clientEndpoint.sendText("again", Callback.NOOP, false);
+        new Thread(pending.poll(5, TimeUnit.SECONDS)).start();
+        // The dropped message is never echoed.
+        assertThat(clientEndpoint.textMessages.poll(5, TimeUnit.SECONDS), is("again"));
+
+        clientEndpoint.sendText("reject", Callback.NOOP, false);
+        assertTrue(clientEndpoint.closeLatch.await(5, TimeUnit.SECONDS));
+        assertThat(clientEndpoint.closeStatus.getCode(), is(CloseStatus.POLICY_VIOLATION));
+        assertThat(clientEndpoint.textMessages.size(), is(0));
+    }
+
+    private class Recorder implements MessageInterceptor
+    {
+        private final String name;
+
+        private Recorder(String name)
+        {
+            this.name = name;
+        }
+
+        @Override
+        public void onInboundMessage(CoreSession coreSession, Message message, Chain chain)
+        {
+            events.add(name + " in " + message.getText());
+            chain.proceed(message);
+        }
+
+        @Override
+        public void onOutboundMessage(CoreSession coreSession, Message message, Chain chain)
+        {
+            events.add(name + " out " + message.getText());
+            chain.proceed(message);
+        }
+    }
+}
@@ -24,6 +24,7 @@ import org.eclipse.jetty.websocket.core.IncomingFramesCapture;
 import org.eclipse.jetty.websocket.core.OutgoingFrames;
 import org.eclipse.jetty.websocket.core.OutgoingFramesCapture;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
+import org.eclipse.jetty.websocket.core.exception.WebSocketException;
 import org.eclipse.jetty.websocket.core.internal.ExtensionStack;
 import org.eclipse.jetty.websocket.core.internal.IdentityExtension;
 import org.junit.jupiter.api.BeforeAll;
@@ -32,8 +33,10 @@ import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
 
 import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
 import static org.hamcrest.Matchers.is;
 import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
 
 public class ExtensionStackTest
 {
@@ -124,4 +127,27 @@ public class ExtensionStackTest
         assertThat("Negotiated Extensions", response, is("permessage-deflate"));
         LOG.debug("Shouldn't cause a NPE: {}", stack.toString());
     }
+
+    @Test
+    public void testServerSkipsRsvConflict()
+    {
+        ExtensionStack serverStack = new ExtensionStack(new WebSocketComponents(), Behavior.SERVER);
+        List<ExtensionConfig> configs = ExtensionConfig.parseList("permessage-deflate, permessage-deflate; client_max_window_bits");
+        serverStack.negotiate(configs, configs);
+
+        // Both extensions claim RSV1, so only the first one is negotiated.
+        String response = ExtensionConfig.toHeaderValue(serverStack.getNegotiatedExtensions());
+        assertThat("Negotiated Extensions", response, is("permessage-deflate"));
+    }
+
+    @Test
+    public void testClientFailsOnRsvConflict()
+    {
+        // Previously the client silently skipped the conflicting extension negotiated by the server.
+        ExtensionStack clientStack = new ExtensionStack(new WebSocketComponents(), Behavior.CLIENT);
+        List<ExtensionConfig> configs = ExtensionConfig.parseList("permessage-deflate, permessage-deflate; client_max_window_bits");
+
+        WebSocketException failure = assertThrows(WebSocketException.class, () -> clientStack.negotiate(configs, configs));
+        assertThat(failure.getMessage(), containsString("RSV1"));
+    }
 }
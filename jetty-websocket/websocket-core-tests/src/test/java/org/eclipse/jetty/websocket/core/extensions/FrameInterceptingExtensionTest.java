@@ -0,0 +1,361 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.core.extensions;
+
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.security.GeneralSecurityException;
+import java.util.List;
+import java.util.Set;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import java.util.stream.Collectors;
+import javax.crypto.Cipher;
+import javax.crypto.SecretKey;
+import javax.crypto.spec.IvParameterSpec;
+import javax.crypto.spec.SecretKeySpec;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.util.BlockingArrayQueue;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.websocket.core.Behavior;
+import org.eclipse.jetty.websocket.core.CloseStatus;
+import org.eclipse.jetty.websocket.core.CoreSession;
+import org.eclipse.jetty.websocket.core.ExtensionConfig;
+import org.eclipse.jetty.websocket.core.Frame;
+import org.eclipse.jetty.websocket.core.FrameInterceptingExtension;
+import org.eclipse.jetty.websocket.core.OpCode;
+import org.eclipse.jetty.websocket.core.TestFrameHandler;
+import org.eclipse.jetty.websocket.core.WebSocketComponents;
+import org.eclipse.jetty.websocket.core.client.CoreClientUpgradeRequest;
+import org.eclipse.jetty.websocket.core.client.WebSocketCoreClient;
+import org.eclipse.jetty.websocket.core.exception.ProtocolException;
+import org.eclipse.jetty.websocket.core.exception.UpgradeException;
+import org.eclipse.jetty.websocket.core.exception.WebSocketException;
+import org.eclipse.jetty.websocket.core.internal.ExtensionStack;
+import org.eclipse.jetty.websocket.core.server.WebSocketNegotiator;
+import org.eclipse.jetty.websocket.core.server.WebSocketUpgradeHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.contains;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.equalTo;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.not;
+import static org.hamcrest.Matchers.notNullValue;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class FrameInterceptingExtensionTest
+{
+    private static final SecretKey KEY = new SecretKeySpec(new byte[16], "AES");
+
+    private Server server;
+    private ServerConnector connector;
+    private WebSocketComponents serverComponents;
+    private WebSocketUpgradeHandler upgradeHandler;
+    private WebSocketComponents clientComponents;
+    private WebSocketCoreClient client;
+
+    @BeforeEach
+    public void before() throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+
+        serverComponents = new WebSocketComponents();
+        registerExtensions(serverComponents);
+        upgradeHandler = new WebSocketUpgradeHandler(serverComponents);
+        server.setHandler(upgradeHandler);
+        server.start();
+
+        clientComponents = new WebSocketComponents();
+        registerExtensions(clientComponents);
+        client = new WebSocketCoreClient(clientComponents);
+        client.start();
+    }
+
+    private static void registerExtensions(WebSocketComponents components)
+    {
+        components.getExtensionRegistry().register("x-noop", NoOpExtension.class);
+        components.getExtensionRegistry().register("x-capture", CaptureExtension.class);
+        components.getExtensionRegistry().register("x-aes", AesExtension.class);
+        components.getExtensionRegistry().register("x-aes-other", AesExtension.class);
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        client.stop();
+        server.stop();
+    }
+
+    private CoreSession connect(TestFrameHandler clientHandler, String... extensions) throws Exception
+    {
+        URI uri = URI.create("ws://localhost:" + connector.getLocalPort());
+        CoreClientUpgradeRequest upgradeRequest = CoreClientUpgradeRequest.from(client, uri, clientHandler);
+        upgradeRequest.addExtensions(extensions);
+        return client.connect(upgradeRequest).get(5, TimeUnit.SECONDS);
+    }
+
+    @Test
+    public void testNoOpExtension() throws Exception
+    {
+        TestFrameHandler serverHandler = new TestFrameHandler();
+        upgradeHandler.addMapping("/", WebSocketNegotiator.from(n -> serverHandler));
+
+        TestFrameHandler clientHandler = new TestFrameHandler();
+        CoreSession coreSession = connect(clientHandler, "x-noop");
+        assertThat(coreSession.getNegotiatedExtensions().get(0).getName(), is("x-noop"));
+        assertTrue(serverHandler.open.await(5, TimeUnit.SECONDS));
+
+        clientHandler.sendText("hello");
+        Frame received = serverHandler.receivedFrames.poll(5, TimeUnit.SECONDS);
+        assertThat(received, notNullValue());
+        assertThat(received.getPayloadAsUTF8(), is("hello"));
+
+        serverHandler.sendText("world");
+        received = clientHandler.receivedFrames.poll(5, TimeUnit.SECONDS);
+        assertThat(received, notNullValue());
+        assertThat(received.getPayloadAsUTF8(), is("world"));
+
+        clientHandler.sendClose();
+        assertTrue(clientHandler.closed.await(5, TimeUnit.SECONDS));
+        assertThat(clientHandler.closeStatus.getCode(), is(CloseStatus.NORMAL));
+    }
+
+    @Test
+    public void testEncryptingExtension() throws Exception
+    {
+        BlockingQueue<Frame> captured = new BlockingArrayQueue<>();
+        serverComponents.setExtensionConfiguration("x-capture", captured);
+        serverComponents.setExtensionConfiguration("x-aes", KEY);
+        clientComponents.setExtensionConfiguration("x-aes", KEY);
+
+        TestFrameHandler serverHandler = new TestFrameHandler();
+        upgradeHandler.addMapping("/", WebSocketNegotiator.from(n -> serverHandler));
+
+        // The capture extension is first, so it is nearest to the network and sees encrypted frames.
+        TestFrameHandler clientHandler = new TestFrameHandler();
+        CoreSession coreSession = connect(clientHandler, "x-capture", "x-aes");
+        assertThat(coreSession.getNegotiatedExtensions().size(), is(2));
+        assertTrue(serverHandler.open.await(5, TimeUnit.SECONDS));
+
+        String message = "hello encrypted world";
+        clientHandler.sendFrame(new Frame(OpCode.TEXT, false, message.substring(0, 6)));
+        clientHandler.sendFrame(new Frame(OpCode.CONTINUATION, true, message.substring(6)));
+
+        Frame first = serverHandler.receivedFrames.poll(5, TimeUnit.SECONDS);
+        Frame second = serverHandler.receivedFrames.poll(5, TimeUnit.SECONDS);
+        assertThat(second, notNullValue());
+        assertThat(first.getPayloadAsUTF8() + second.getPayloadAsUTF8(), is(message));
+        assertThat(first.isRsv2(), is(false));
+
+        Frame wire = captured.poll(5, TimeUnit.SECONDS);
+        assertThat(wire, notNullValue());
+        assertThat(wire.isRsv2(), is(true));
+        assertThat(wire.getPayloadLength(), is(6));
+        assertThat(wire.getPayload(), not(equalTo(ByteBuffer.wrap(message.substring(0, 6).getBytes(StandardCharsets.UTF_8)))));
+
+        serverHandler.sendText("reply");
+        Frame reply = clientHandler.receivedFrames.poll(5, TimeUnit.SECONDS);
+        assertThat(reply, notNullValue());
+        assertThat(reply.getPayloadAsUTF8(), is("reply"));
+
+        clientHandler.sendClose();
+        assertTrue(clientHandler.closed.await(5, TimeUnit.SECONDS));
+        assertThat(clientHandler.closeStatus.getCode(), is(CloseStatus.NORMAL));
+        // Control frames are not encrypted.
+        Frame close = captured.stream().filter(f -> f.getOpCode() == OpCode.CLOSE).findFirst().orElseThrow();
+        assertThat(close.isRsv2(), is(false));
+    }
+
+    @Test
+    public void testUnencryptedFrameIsProtocolError() throws Exception
+    {
+        serverComponents.setExtensionConfiguration("x-aes", KEY);
+
+        TestFrameHandler serverHandler = new TestFrameHandler();
+        upgradeHandler.addMapping("/", WebSocketNegotiator.from(n -> serverHandler));
+
+        // The client does not encrypt, but the server expects encrypted frames.
+        TestFrameHandler clientHandler = new TestFrameHandler();
+        clientComponents.getExtensionRegistry().unregister("x-aes");
+        clientComponents.getExtensionRegistry().register("x-aes", NoOpExtension.class);
+        connect(clientHandler, "x-aes");
+
+        clientHandler.sendText("plain text");
+        assertTrue(clientHandler.closed.await(5, TimeUnit.SECONDS));
+        assertThat(clientHandler.closeStatus.getCode(), is(CloseStatus.PROTOCOL));
+        assertThat(serverHandler.receivedFrames.stream().anyMatch(Frame::isDataFrame), is(false));
+    }
+
+    @Test
+    public void testUnsupportedParameterRejected() throws Exception
+    {
+        TestFrameHandler serverHandler = new TestFrameHandler();
+        upgradeHandler.addMapping("/", WebSocketNegotiator.from(n -> serverHandler));
+
+        // x-aes supports no parameters, so both the client and the server reject it.
+        TestFrameHandler clientHandler = new TestFrameHandler();
+        Throwable failure = assertThrows(ExecutionException.class, () -> connect(clientHandler, "x-aes; mode=ecb")).getCause();
+        assertThat(failure, instanceOf(UpgradeException.class));
+    }
+
+    @Test
+    public void testRsvConflictOnServer()
+    {
+        ExtensionStack stack = new ExtensionStack(serverComponents, Behavior.SERVER);
+        List<ExtensionConfig> configs = List.of(ExtensionConfig.parse("x-aes"), ExtensionConfig.parse("x-noop"), ExtensionConfig.parse("x-aes-other"));
+        stack.negotiate(configs, configs);
+
+        // The second extension claiming RSV2 is not negotiated.
+        List<String> names = stack.getNegotiatedExtensions().stream().map(ExtensionConfig::getName).collect(Collectors.toList());
+        assertThat(names, contains("x-aes", "x-noop"));
+    }
+
+    @Test
+    public void testRsvConflictOnClient()
+    {
+        ExtensionStack stack = new ExtensionStack(clientComponents, Behavior.CLIENT);
+        List<ExtensionConfig> configs = List.of(ExtensionConfig.parse("x-aes"), ExtensionConfig.parse("x-aes-other"));
+
+        WebSocketException failure = assertThrows(WebSocketException.class, () -> stack.negotiate(configs, configs));
+        assertThat(failure.getMessage(), containsString("RSV2"));
+    }
+
+    public static class NoOpExtension extends FrameInterceptingExtension
+    {
+    }
+
+    /**
+     * Records the incoming frames into the queue configured in the components, if any.
+     */
+    public static class CaptureExtension extends FrameInterceptingExtension
+    {
+        @Override
+        protected void interceptIncomingFrame(Frame frame, Callback callback)
+        {
+            @SuppressWarnings("unchecked")
+            BlockingQueue<Frame> captured = getExtensionConfiguration(BlockingQueue.class);
+            if (captured != null)
+                captured.offer(Frame.copy(frame));
+            forwardIncomingFrame(frame, callback);
+        }
+    }
+
+    /**
+     * Encrypts the payload of data frames with AES/CTR, using the key configured
+     * in the components and a different IV for each direction, and marks them with RSV2.
+     */
+    public static class AesExtension extends FrameInterceptingExtension
+    {
+        private static final byte[] CLIENT_IV = new byte[16];
+        private static final byte[] SERVER_IV = new byte[16];
+
+        static
+        {
+            SERVER_IV[0] = 1;
+        }
+
+        private Cipher encrypter;
+        private Cipher decrypter;
+
+        @Override
+        protected Set<String> getSupportedParameters()
+        {
+            return Set.of();
+        }
+
+        @Override
+        public boolean isRsv2User()
+        {
+            return true;
+        }
+
+        @Override
+        public void setCoreSession(CoreSession coreSession)
+        {
+            super.setCoreSession(coreSession);
+            SecretKey key = getExtensionConfiguration(SecretKey.class);
+            if (key == null)
+                throw new IllegalStateException("No key configured for " + getName());
+            boolean server = coreSession.getBehavior() == Behavior.SERVER;
+            encrypter = newCipher(Cipher.ENCRYPT_MODE, key, server ? SERVER_IV : CLIENT_IV);
+            decrypter = newCipher(Cipher.DECRYPT_MODE, key, server ? CLIENT_IV : SERVER_IV);
+        }
+
+        @Override
+        protected void interceptIncomingFrame(Frame frame, Callback callback)
+        {
+            if (!frame.isDataFrame())
+            {
+                forwardIncomingFrame(frame, callback);
+                return;
+            }
+            if (!frame.isRsv2())
+                throw new ProtocolException("Unencrypted " + OpCode.name(frame.getOpCode()) + " frame");
+            forwardIncomingFrame(new Frame(frame.getOpCode(), frame.isFin(), transform(decrypter, frame.getPayload())), callback);
+        }
+
+        @Override
+        protected void interceptOutgoingFrame(Frame frame, Callback callback, boolean batch)
+        {
+            if (!frame.isDataFrame())
+            {
+                forwardOutgoingFrame(frame, callback, batch);
+                return;
+            }
+            Frame encrypted = new Frame(frame.getOpCode(), frame.isFin(), transform(encrypter, frame.getPayload()));
+            encrypted.setRsv2(true);
+            forwardOutgoingFrame(encrypted, callback, batch);
+        }
+
+        private static Cipher newCipher(int mode, SecretKey key, byte[] iv)
+        {
+            try
+            {
+                Cipher cipher = Cipher.getInstance("AES/CTR/NoPadding");
+                cipher.init(mode, key, new IvParameterSpec(iv));
+                return cipher;
+            }
+            catch (GeneralSecurityException x)
+            {
+                throw new IllegalStateException(x);
+            }
+        }
+
+        private static ByteBuffer transform(Cipher cipher, ByteBuffer input)
+        {
+            try
+            {
+                ByteBuffer output = ByteBuffer.allocate(input.remaining());
+                cipher.update(input.slice(), output);
+                return output.flip();
+            }
+            catch (GeneralSecurityException x)
+            {
+                throw new IllegalStateException(x);
+            }
+        }
+    }
+}
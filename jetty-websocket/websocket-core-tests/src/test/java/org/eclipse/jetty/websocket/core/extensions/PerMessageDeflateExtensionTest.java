@@ -525,6 +525,59 @@ public class PerMessageDeflateExtensionTest extends AbstractExtensionTest
         //assertThat("Frame.payload", actual.getPayload(), is(BufferUtil.EMPTY_BUFFER));
     }
 
+    @Test
+    public void testOutgoingUncompressedWhenNoDeflaterContext() throws Exception
+    {
+        components.setMaxDeflaterContexts(1);
+        PerMessageDeflateExtension ext1 = new PerMessageDeflateExtension();
+        ext1.init(ExtensionConfig.parse("permessage-deflate"), components);
+        ext1.setCoreSession(newSession());
+        OutgoingFramesCapture capture1 = new OutgoingFramesCapture();
+        ext1.setNextOutgoingFrames(capture1);
+
+        PerMessageDeflateExtension ext2 = new PerMessageDeflateExtension();
+        ext2.init(ExtensionConfig.parse("permessage-deflate"), components);
+        ext2.setCoreSession(newSession());
+        OutgoingFramesCapture capture2 = new OutgoingFramesCapture();
+        ext2.setNextOutgoingFrames(capture2);
+
+        String payload = "Hello Hello Hello Hello Hello Hello Hello Hello";
+        ext1.sendFrame(new Frame(OpCode.TEXT, true, payload), Callback.NOOP, false);
+        ext2.sendFrame(new Frame(OpCode.TEXT, true, payload), Callback.NOOP, false);
+
+        Frame compressed = capture1.frames.poll(1, TimeUnit.SECONDS);
+        assertThat("Frame.rsv1", compressed.isRsv1(), is(true));
+        assertThat(components.getDeflaterContexts(), is(1));
+        assertThat(ext1.getBytesDeflatedIn(), is((long)payload.length()));
+        assertThat(ext1.getBytesDeflatedOut(), is((long)compressed.getPayloadLength()));
+        assertThat(ext1.getOutgoingCompressionRatio() < 1, is(true));
+
+        Frame uncompressed = capture2.frames.poll(1, TimeUnit.SECONDS);
+        assertThat("Frame.rsv1", uncompressed.isRsv1(), is(false));
+        assertThat(uncompressed.getPayloadAsUTF8(), is(payload));
+        assertThat(ext2.getMessagesUncompressed(), is(1L));
+
+        // Once the first context is released, the other session can compress.
+        ext1.close();
+        assertThat(components.getDeflaterContexts(), is(0));
+        ext2.sendFrame(new Frame(OpCode.TEXT, true, payload), Callback.NOOP, false);
+        assertThat("Frame.rsv1", capture2.frames.poll(1, TimeUnit.SECONDS).isRsv1(), is(true));
+        ext2.close();
+        assertThat(components.getDeflaterContexts(), is(0));
+    }
+
+    @Test
+    public void testServerMaxWindowBitsNegotiation()
+    {
+        PerMessageDeflateExtension ext = new PerMessageDeflateExtension();
+        ext.init(ExtensionConfig.parse("permessage-deflate; server_max_window_bits=15; client_max_window_bits"), components);
+        assertThat(ext.getConfig().getParameterizedName(), is("permessage-deflate;server_max_window_bits=15"));
+
+        ext = new PerMessageDeflateExtension();
+        ext.init(ExtensionConfig.parse("permessage-deflate; server_max_window_bits=10"), components);
+        assertThat(ext.getConfig().getParameterizedName(), is("permessage-deflate"));
+    }
+
     @Test
     public void testPyWebSocketClientNoContextTakeoverThreeOra()
     {
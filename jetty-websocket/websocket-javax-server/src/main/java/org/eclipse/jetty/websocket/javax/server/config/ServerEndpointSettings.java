@@ -0,0 +1,57 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.javax.server.config;
+
+import java.lang.annotation.Documented;
+import java.lang.annotation.ElementType;
+import java.lang.annotation.Retention;
+import java.lang.annotation.RetentionPolicy;
+import java.lang.annotation.Target;
+
+/**
+ * <p>Declares Jetty specific settings for the sessions of a server endpoint.</p>
+ * <p>This annotation supplements {@link javax.websocket.server.ServerEndpoint}, or can be applied to
+ * a {@link javax.websocket.Endpoint} subclass deployed with a {@link javax.websocket.server.ServerEndpointConfig},
+ * so that the endpoint does not depend on the container wide defaults.
+ * The settings are resolved and validated when the endpoint is deployed, and a
+ * {@link javax.websocket.DeploymentException} is thrown if they are invalid.</p>
+ * <p>A value of {@code -1} means that the container default is used.
+ * A {@link javax.websocket.OnMessage#maxMessageSize()} declared on a message method
+ * takes precedence over the message sizes declared here.</p>
+ */
+@Documented
+@Retention(RetentionPolicy.RUNTIME)
+@Target(ElementType.TYPE)
+public @interface ServerEndpointSettings
+{
+    /**
+     * The maximum size of a text message in bytes, which must be positive.
+     */
+    long maxTextMessageSize() default -1;
+
+    /**
+     * The maximum size of a binary message in bytes, which must be positive.
+     */
+    long maxBinaryMessageSize() default -1;
+
+    /**
+     * The time in milliseconds that a session may be idle before closing, or {@code 0} to never close idle sessions.
+     */
+    long idleTimeout() default -1;
+
+    /**
+     * The size in bytes of the buffer used to read from the network, which must be positive.
+     */
+    int inputBufferSize() default -1;
+}
@@ -0,0 +1,132 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.javax.server.internal;
+
+import java.time.Duration;
+import javax.websocket.DeploymentException;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.websocket.core.Configuration;
+import org.eclipse.jetty.websocket.javax.server.config.ServerEndpointSettings;
+
+/**
+ * <p>The resolved {@link ServerEndpointSettings} of a deployed endpoint.</p>
+ * <p>Sessions of the endpoint are first customized with the container defaults,
+ * then with the settings declared by the endpoint.</p>
+ */
+@ManagedObject("The settings of a JSR356 server endpoint")
+public class JavaxServerEndpointSettings implements Configuration.Customizer
+{
+    private final String path;
+    private final Class<?> endpointClass;
+    private final Configuration.Customizer defaults;
+    private final long maxTextMessageSize;
+    private final long maxBinaryMessageSize;
+    private final long idleTimeout;
+    private final int inputBufferSize;
+
+    /**
+     * @param path the path the endpoint is mapped to
+     * @param endpointClass the endpoint class
+     * @param defaults the container defaults, applied before the endpoint settings
+     * @return the settings declared by the endpoint class, or null if it declares none
+     * @throws DeploymentException if the declared settings are invalid
+     */
+    public static JavaxServerEndpointSettings from(String path, Class<?> endpointClass, Configuration.Customizer defaults) throws DeploymentException
+    {
+        ServerEndpointSettings anno = endpointClass.getAnnotation(ServerEndpointSettings.class);
+        if (anno == null)
+            return null;
+        return new JavaxServerEndpointSettings(path, endpointClass, anno, defaults);
+    }
+
+    private JavaxServerEndpointSettings(String path, Class<?> endpointClass, ServerEndpointSettings anno, Configuration.Customizer defaults) throws DeploymentException
+    {
+        this.path = path;
+        this.endpointClass = endpointClass;
+        this.defaults = defaults;
+        this.maxTextMessageSize = validate("maxTextMessageSize", anno.maxTextMessageSize(), 1);
+        this.maxBinaryMessageSize = validate("maxBinaryMessageSize", anno.maxBinaryMessageSize(), 1);
+        this.idleTimeout = validate("idleTimeout", anno.idleTimeout(), 0);
+        this.inputBufferSize = (int)validate("inputBufferSize", anno.inputBufferSize(), 1);
+    }
+
+    private long validate(String name, long value, long min) throws DeploymentException
+    {
+        if (value == -1 || value >= min)
+            return value;
+        throw new DeploymentException(String.format("Invalid @%s %s=%d for endpoint %s: must be >= %d",
+            ServerEndpointSettings.class.getSimpleName(), name, value, endpointClass.getName(), min));
+    }
+
+    @ManagedAttribute("The path the endpoint is mapped to")
+    public String getPath()
+    {
+        return path;
+    }
+
+    @ManagedAttribute("The endpoint class name")
+    public String getEndpointClassName()
+    {
+        return endpointClass.getName();
+    }
+
+    @ManagedAttribute("The max text message size, or -1 for the container default")
+    public long getMaxTextMessageSize()
+    {
+        return maxTextMessageSize;
+    }
+
+    @ManagedAttribute("The max binary message size, or -1 for the container default")
+    public long getMaxBinaryMessageSize()
+    {
+        return maxBinaryMessageSize;
+    }
+
+    @ManagedAttribute("The idle timeout in ms, or -1 for the container default")
+    public long getIdleTimeout()
+    {
+        return idleTimeout;
+    }
+
+    @ManagedAttribute("The input buffer size, or -1 for the container default")
+    public int getInputBufferSize()
+    {
+        return inputBufferSize;
+    }
+
+    @Override
+    public void customize(Configuration configurable)
+    {
+        if (defaults != null)
+            defaults.customize(configurable);
+        if (maxTextMessageSize >= 0)
+            configurable.setMaxTextMessageSize(maxTextMessageSize);
+        if (maxBinaryMessageSize >= 0)
+            configurable.setMaxBinaryMessageSize(maxBinaryMessageSize);
+        if (idleTimeout >= 0)
+            configurable.setIdleTimeout(Duration.ofMillis(idleTimeout));
+        if (inputBufferSize >= 0)
+            configurable.setInputBufferSize(inputBufferSize);
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x{path=%s,endpoint=%s,maxTextMessageSize=%d,maxBinaryMessageSize=%d,idleTimeout=%d,inputBufferSize=%d}",
+            getClass().getSimpleName(), hashCode(), path, endpointClass.getName(),
+            maxTextMessageSize, maxBinaryMessageSize, idleTimeout, inputBufferSize);
+    }
+}
@@ -34,6 +34,7 @@ import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.servlet.ServletContextHandler;
 import org.eclipse.jetty.util.annotation.ManagedObject;
 import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
 import org.eclipse.jetty.websocket.core.client.WebSocketCoreClient;
 import org.eclipse.jetty.websocket.core.exception.InvalidSignatureException;
@@ -248,14 +249,28 @@ public class JavaxWebSocketServerContainer extends JavaxWebSocketClientContainer
         }
     }
 
+    /**
+     * <p>Adds a {@link MessageInterceptor} to the messages of the endpoints deployed at the given path.</p>
+     *
+     * @param path the endpoint path, as in {@link ServerEndpointConfig#getPath()}
+     * @param interceptor the interceptor, applied after the interceptors already added
+     */
+    public void addMessageInterceptor(String path, MessageInterceptor interceptor)
+    {
+        webSocketMappings.addMessageInterceptor(new UriTemplatePathSpec(path), interceptor);
+    }
+
     private void addEndpointMapping(ServerEndpointConfig config) throws DeploymentException
     {
+        JavaxServerEndpointSettings settings = JavaxServerEndpointSettings.from(config.getPath(), config.getEndpointClass(), defaultCustomizer);
         try
         {
             frameHandlerFactory.getMetadata(config.getEndpointClass(), config);
             JavaxWebSocketCreator creator = new JavaxWebSocketCreator(this, config, getExtensionRegistry());
             PathSpec pathSpec = new UriTemplatePathSpec(config.getPath());
-            webSocketMappings.addMapping(pathSpec, creator, frameHandlerFactory, defaultCustomizer);
+            webSocketMappings.addMapping(pathSpec, creator, frameHandlerFactory, settings == null ? defaultCustomizer : settings);
+            if (settings != null)
+                addBean(settings);
         }
         catch (InvalidSignatureException e)
         {
@@ -286,12 +301,13 @@ public class JavaxWebSocketServerContainer extends JavaxWebSocketClientContainer
             LOG.debug("addEndpoint({}) path={} endpoint={}", config, config.getPath(), endpointClass);
 
         validateEndpointConfig(config);
+        JavaxServerEndpointSettings settings = JavaxServerEndpointSettings.from(config.getPath(), config.getEndpointClass(), null);
         frameHandlerFactory.getMetadata(config.getEndpointClass(), config);
         request.setAttribute(JavaxWebSocketServerContainer.PATH_PARAM_ATTRIBUTE, pathParameters);
 
         // Perform the upgrade.
         JavaxWebSocketCreator creator = new JavaxWebSocketCreator(this, config, getExtensionRegistry());
-        WebSocketNegotiator negotiator = WebSocketNegotiator.from(creator, frameHandlerFactory);
+        WebSocketNegotiator negotiator = WebSocketNegotiator.from(creator, frameHandlerFactory, settings);
         Handshaker handshaker = webSocketMappings.getHandshaker();
         handshaker.upgradeRequest(negotiator, request, response, components, defaultCustomizer);
     }
@@ -0,0 +1,176 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.javax.tests.server;
+
+import java.net.URI;
+import java.util.concurrent.TimeUnit;
+import javax.websocket.CloseReason;
+import javax.websocket.ContainerProvider;
+import javax.websocket.DeploymentException;
+import javax.websocket.OnMessage;
+import javax.websocket.Session;
+import javax.websocket.WebSocketContainer;
+import javax.websocket.server.ServerContainer;
+import javax.websocket.server.ServerEndpoint;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.websocket.javax.server.config.JavaxWebSocketServletContainerInitializer;
+import org.eclipse.jetty.websocket.javax.server.config.ServerEndpointSettings;
+import org.eclipse.jetty.websocket.javax.server.internal.JavaxServerEndpointSettings;
+import org.eclipse.jetty.websocket.javax.server.internal.JavaxWebSocketServerContainer;
+import org.eclipse.jetty.websocket.javax.tests.EventSocket;
+import org.eclipse.jetty.websocket.javax.tests.WSURI;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class ServerEndpointSettingsTest
+{
+    private Server server;
+    private ServerConnector connector;
+    private ServletContextHandler contextHandler;
+    private WebSocketContainer client;
+    private ServerContainer serverContainer;
+
+    @ServerEndpoint("/settings")
+    @ServerEndpointSettings(maxTextMessageSize = 64, idleTimeout = 12345, inputBufferSize = 2048)
+    public static class SettingsSocket
+    {
+        @OnMessage
+        public String onMessage(Session session, String message)
+        {
+            return session.getMaxTextMessageBufferSize() + "/" + session.getMaxIdleTimeout();
+        }
+    }
+
+    @ServerEndpoint("/defaults")
+    public static class DefaultsSocket
+    {
+        @OnMessage
+        public String onMessage(Session session, String message)
+        {
+            return session.getMaxTextMessageBufferSize() + "/" + session.getMaxIdleTimeout();
+        }
+    }
+
+    @ServerEndpoint("/invalid")
+    @ServerEndpointSettings(inputBufferSize = 0)
+    public static class InvalidSettingsSocket
+    {
+        @OnMessage
+        public void onMessage(String message)
+        {
+        }
+    }
+
+    @BeforeEach
+    public void before()
+    {
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+
+        contextHandler = new ServletContextHandler();
+        contextHandler.setContextPath("/");
+        server.setHandler(contextHandler);
+        client = ContainerProvider.getWebSocketContainer();
+    }
+
+    @AfterEach
+    public void after() throws Exception
+    {
+        LifeCycle.stop(client);
+        server.stop();
+    }
+
+    private void start(Class<?>... endpoints) throws Exception
+    {
+        JavaxWebSocketServletContainerInitializer.configure(contextHandler, (context, container) ->
+        {
+            container.setDefaultMaxTextMessageBufferSize(32 * 1024);
+            container.setDefaultMaxSessionIdleTimeout(60000);
+            for (Class<?> endpoint : endpoints)
+            {
+                container.addEndpoint(endpoint);
+            }
+            serverContainer = container;
+        });
+        server.start();
+    }
+
+    private String roundTrip(String path, String message) throws Exception
+    {
+        EventSocket clientSocket = new EventSocket();
+        URI uri = WSURI.toWebsocket(server.getURI().resolve(path));
+        Session session = client.connectToServer(clientSocket, uri);
+        try
+        {
+            session.getBasicRemote().sendText(message);
+            return clientSocket.textMessages.poll(5, TimeUnit.SECONDS);
+        }
+        finally
+        {
+            session.close();
+        }
+    }
+
+    @Test
+    public void testEndpointSettingsOverrideContainerDefaults() throws Exception
+    {
+        start(SettingsSocket.class, DefaultsSocket.class);
+
+        assertThat(roundTrip("/settings", "hello"), is("64/12345"));
+        assertThat(roundTrip("/defaults", "hello"), is((32 * 1024) + "/60000"));
+
+        // The settings are exposed as a bean of the container.
+        JavaxServerEndpointSettings settings = ((JavaxWebSocketServerContainer)serverContainer).getBean(JavaxServerEndpointSettings.class);
+        assertThat(settings.getPath(), is("/settings"));
+        assertThat(settings.getEndpointClassName(), is(SettingsSocket.class.getName()));
+        assertThat(settings.getMaxTextMessageSize(), is(64L));
+        assertThat(settings.getMaxBinaryMessageSize(), is(-1L));
+        assertThat(settings.getInputBufferSize(), is(2048));
+    }
+
+    @Test
+    public void testMessageTooLarge() throws Exception
+    {
+        start(SettingsSocket.class);
+
+        EventSocket clientSocket = new EventSocket();
+        URI uri = WSURI.toWebsocket(server.getURI().resolve("/settings"));
+        Session session = client.connectToServer(clientSocket, uri);
+        session.getBasicRemote().sendText("x".repeat(128));
+
+        assertTrue(clientSocket.closeLatch.await(5, TimeUnit.SECONDS));
+        assertThat(clientSocket.closeReason.getCloseCode(), is(CloseReason.CloseCodes.TOO_BIG));
+    }
+
+    @Test
+    public void testInvalidSettings()
+    {
+        RuntimeException error = assertThrows(RuntimeException.class, () -> start(InvalidSettingsSocket.class));
+        assertThat(error.getCause(), instanceOf(DeploymentException.class));
+        assertThat(error.getCause().getMessage(), containsString("inputBufferSize=0"));
+    }
+}
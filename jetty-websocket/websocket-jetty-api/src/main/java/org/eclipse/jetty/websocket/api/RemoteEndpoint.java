@@ -14,6 +14,7 @@
 package org.eclipse.jetty.websocket.api;
 
 import java.io.IOException;
+import java.io.InputStream;
 import java.net.SocketAddress;
 import java.nio.ByteBuffer;
 
@@ -62,6 +63,37 @@ public interface RemoteEndpoint
      */
     void sendPartialBytes(ByteBuffer fragment, boolean isLast, WriteCallback callback);
 
+    /**
+     * Send a binary message read from the given stream, blocking until all of the message has been transmitted.
+     * <p>
+     * The message is sent in fragments no larger than the output buffer size and the max frame size of the session.
+     * If the {@link #setOutgoingWatermarks(long, long, WatermarkListener) outgoing watermarks} are configured,
+     * fragments are not sent while the outgoing bytes are above the high watermark, until they drop
+     * to the low watermark.
+     * The stream is closed when the message has been sent or the send has failed.
+     * <p>
+     * Note: this is a blocking call
+     *
+     * @param stream the stream of the message content
+     * @throws IOException if unable to read the stream or to send the bytes
+     */
+    void sendStream(InputStream stream) throws IOException;
+
+    /**
+     * Initiates the asynchronous transmission of a binary message read from the given stream.
+     * This method returns before the message is transmitted.
+     * <p>
+     * The message is fragmented as described in {@link #sendStream(InputStream)}; the next fragment is
+     * read from the stream only when the previous one has been transmitted, from the thread that completed
+     * the previous write, so the stream should not block for long periods of time.
+     * The stream is closed when the message has been sent or the send has failed.
+     * Developers may provide a callback to be notified when the message has been transmitted or resulted in an error.
+     *
+     * @param stream the stream of the message content
+     * @param callback callback to notify of success or failure of the write operation
+     */
+    void sendStream(InputStream stream, WriteCallback callback);
+
     /**
      * Send a text message, blocking until all bytes of the message has been transmitted.
      * <p>
@@ -181,6 +213,25 @@ public interface RemoteEndpoint
      */
     void setMaxOutgoingFrames(int maxOutgoingFrames);
 
+    /**
+     * @return the number of payload bytes of the frames that have been sent but not yet transmitted
+     */
+    long getOutgoingBytes();
+
+    /**
+     * <p>Configures the watermarks of the outgoing bytes.</p>
+     * <p>When the {@link #getOutgoingBytes() outgoing bytes} exceed the high watermark,
+     * {@link WatermarkListener#onHighWatermark(RemoteEndpoint)} is invoked, so that
+     * applications may pause their message producers; when the outgoing bytes drop
+     * back to the low watermark, {@link WatermarkListener#onLowWatermark(RemoteEndpoint)}
+     * is invoked, so that applications may resume their message producers.</p>
+     *
+     * @param lowWatermark the low watermark in bytes
+     * @param highWatermark the high watermark in bytes, or 0 to disable the watermarks
+     * @param listener the listener notified of watermark events, or null
+     */
+    void setOutgoingWatermarks(long lowWatermark, long highWatermark, WatermarkListener listener);
+
     /**
      * Get the SocketAddress for the established connection.
      *
@@ -194,4 +245,30 @@ public interface RemoteEndpoint
      * @throws IOException if the flush fails
      */
     void flush() throws IOException;
+
+    /**
+     * <p>A listener for the outgoing bytes watermark events.</p>
+     *
+     * @see #setOutgoingWatermarks(long, long, WatermarkListener)
+     */
+    interface WatermarkListener
+    {
+        /**
+         * <p>Invoked when the outgoing bytes exceed the high watermark.</p>
+         *
+         * @param remote the remote endpoint
+         */
+        default void onHighWatermark(RemoteEndpoint remote)
+        {
+        }
+
+        /**
+         * <p>Invoked when the outgoing bytes drop back to the low watermark.</p>
+         *
+         * @param remote the remote endpoint
+         */
+        default void onLowWatermark(RemoteEndpoint remote)
+        {
+        }
+    }
 }
@@ -0,0 +1,705 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.client;
+
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.util.ArrayDeque;
+import java.util.ArrayList;
+import java.util.EventListener;
+import java.util.List;
+import java.util.Queue;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CopyOnWriteArrayList;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.function.Supplier;
+
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.util.thread.Scheduler;
+import org.eclipse.jetty.websocket.api.Session;
+import org.eclipse.jetty.websocket.api.StatusCode;
+import org.eclipse.jetty.websocket.api.WebSocketConnectionListener;
+import org.eclipse.jetty.websocket.api.WebSocketListener;
+import org.eclipse.jetty.websocket.api.WriteCallback;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A WebSocket client connection that is automatically re-established when it closes abnormally.</p>
+ * <p>The application endpoint must implement {@link WebSocketConnectionListener}, and may also
+ * implement {@link WebSocketListener} to receive whole text and binary messages; a new
+ * {@link Session} is passed to {@link WebSocketConnectionListener#onWebSocketConnect(Session)}
+ * for each connection.</p>
+ * <p>When the connection closes with a status code for which {@link #shouldReconnect(int, String)}
+ * returns {@code true}, or a reconnection attempt fails, a new connection attempt is scheduled
+ * with exponential backoff and jitter, from {@link #getInitialBackoff()} up to {@link #getMaxBackoff()},
+ * until {@link #getMaxReconnectAttempts()} consecutive attempts have failed.</p>
+ * <p>For each connection, a new upgrade request is obtained via {@link #setUpgradeRequestSupplier(Supplier)},
+ * for example to send a resume token, and then the {@link ResumeHandler} is called to restore the
+ * application state, for example to re-subscribe to topics, before any buffered message is sent.</p>
+ * <p>Messages sent with {@link #sendString(String, WriteCallback)} and {@link #sendBytes(ByteBuffer, WriteCallback)}
+ * while disconnected are buffered, up to {@link #getMaxBufferedMessages()} messages
+ * and {@link #getMaxBufferedBytes()} bytes, and sent in order once the connection is re-established.</p>
+ */
+public class ReconnectingWebSocketClient extends ContainerLifeCycle
+{
+    private static final Logger LOG = LoggerFactory.getLogger(ReconnectingWebSocketClient.class);
+
+    private final AutoLock lock = new AutoLock();
+    private final List<Listener> listeners = new CopyOnWriteArrayList<>();
+    private final Queue<Message> messages = new ArrayDeque<>();
+    private final WebSocketClient client;
+    private final WebSocketConnectionListener endpoint;
+    private final URI uri;
+    private Supplier<ClientUpgradeRequest> upgradeRequestSupplier = ClientUpgradeRequest::new;
+    private ResumeHandler resumeHandler;
+    private long initialBackoff = 500;
+    private long maxBackoff = 30000;
+    private double backoffMultiplier = 2;
+    private int maxReconnectAttempts = -1;
+    private int maxBufferedMessages = 1024;
+    private long maxBufferedBytes = 1024 * 1024;
+    private State state = State.IDLE;
+    private EndpointAdapter current;
+    private Session session;
+    private Scheduler.Task reconnectTask;
+    private long bufferedBytes;
+    private int attempts;
+
+    /**
+     * @param client the WebSocketClient used to connect
+     * @param endpoint the application endpoint, implementing {@link WebSocketConnectionListener}
+     * @param uri the WebSocket URI to connect to
+     */
+    public ReconnectingWebSocketClient(WebSocketClient client, WebSocketConnectionListener endpoint, URI uri)
+    {
+        this.client = client;
+        this.endpoint = endpoint;
+        this.uri = uri;
+        addBean(client);
+    }
+
+    public WebSocketClient getWebSocketClient()
+    {
+        return client;
+    }
+
+    public URI getURI()
+    {
+        return uri;
+    }
+
+    public Supplier<ClientUpgradeRequest> getUpgradeRequestSupplier()
+    {
+        return upgradeRequestSupplier;
+    }
+
+    /**
+     * @param upgradeRequestSupplier the supplier of the upgrade request used for each connection attempt
+     */
+    public void setUpgradeRequestSupplier(Supplier<ClientUpgradeRequest> upgradeRequestSupplier)
+    {
+        this.upgradeRequestSupplier = upgradeRequestSupplier;
+    }
+
+    public ResumeHandler getResumeHandler()
+    {
+        return resumeHandler;
+    }
+
+    /**
+     * @param resumeHandler the handler called for each connection before buffered messages are sent
+     */
+    public void setResumeHandler(ResumeHandler resumeHandler)
+    {
+        this.resumeHandler = resumeHandler;
+    }
+
+    /**
+     * @return the delay in milliseconds before the first reconnection attempt
+     */
+    public long getInitialBackoff()
+    {
+        return initialBackoff;
+    }
+
+    public void setInitialBackoff(long initialBackoff)
+    {
+        this.initialBackoff = initialBackoff;
+    }
+
+    /**
+     * @return the max delay in milliseconds between reconnection attempts
+     */
+    public long getMaxBackoff()
+    {
+        return maxBackoff;
+    }
+
+    public void setMaxBackoff(long maxBackoff)
+    {
+        this.maxBackoff = maxBackoff;
+    }
+
+    /**
+     * @return the factor by which the delay grows after each failed reconnection attempt
+     */
+    public double getBackoffMultiplier()
+    {
+        return backoffMultiplier;
+    }
+
+    public void setBackoffMultiplier(double backoffMultiplier)
+    {
+        if (backoffMultiplier < 1)
+            throw new IllegalArgumentException("Invalid backoff multiplier " + backoffMultiplier);
+        this.backoffMultiplier = backoffMultiplier;
+    }
+
+    /**
+     * @return the max number of consecutive failed reconnection attempts, or -1 for no limit
+     */
+    public int getMaxReconnectAttempts()
+    {
+        return maxReconnectAttempts;
+    }
+
+    public void setMaxReconnectAttempts(int maxReconnectAttempts)
+    {
+        this.maxReconnectAttempts = maxReconnectAttempts;
+    }
+
+    /**
+     * @return the max number of messages buffered while disconnected
+     */
+    public int getMaxBufferedMessages()
+    {
+        return maxBufferedMessages;
+    }
+
+    public void setMaxBufferedMessages(int maxBufferedMessages)
+    {
+        this.maxBufferedMessages = maxBufferedMessages;
+    }
+
+    /**
+     * @return the max number of bytes of the messages buffered while disconnected
+     */
+    public long getMaxBufferedBytes()
+    {
+        return maxBufferedBytes;
+    }
+
+    public void setMaxBufferedBytes(long maxBufferedBytes)
+    {
+        this.maxBufferedBytes = maxBufferedBytes;
+    }
+
+    public void addListener(Listener listener)
+    {
+        listeners.add(listener);
+    }
+
+    public boolean removeListener(Listener listener)
+    {
+        return listeners.remove(listener);
+    }
+
+    public State getState()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return state;
+        }
+    }
+
+    /**
+     * @return the current session, or {@code null} if not connected
+     */
+    public Session getSession()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return state == State.OPEN ? session : null;
+        }
+    }
+
+    /**
+     * @return the number of messages buffered while disconnected
+     */
+    public int getBufferedMessages()
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            return messages.size();
+        }
+    }
+
+    /**
+     * <p>Connects to the WebSocket URI, starting this component if necessary.</p>
+     * <p>The returned future completes when the first connection is established;
+     * failures of the first connection attempt are retried like those of reconnections.</p>
+     *
+     * @return a future completed with the session of the first connection
+     * @throws Exception if this component cannot be started
+     */
+    public CompletableFuture<Session> connect() throws Exception
+    {
+        if (!isStarted())
+            start();
+        CompletableFuture<Session> result = new CompletableFuture<>();
+        try (AutoLock ignored = lock.lock())
+        {
+            if (state != State.IDLE)
+                throw new IllegalStateException("Already connected " + this);
+        }
+        addListener(new Listener()
+        {
+            @Override
+            public void onStateChange(ReconnectingWebSocketClient client, State state, Throwable failure)
+            {
+                if (state == State.OPEN)
+                {
+                    removeListener(this);
+                    result.complete(getSession());
+                }
+                else if (state == State.CLOSED)
+                {
+                    removeListener(this);
+                    result.completeExceptionally(failure != null ? failure : new ClosedChannelException());
+                }
+            }
+        });
+        doConnect();
+        return result;
+    }
+
+    /**
+     * <p>Sends a text message, or buffers it if the connection is not open.</p>
+     *
+     * @param text the text message
+     * @param callback the callback completed when the message is sent, or fails to be sent or buffered
+     */
+    public void sendString(String text, WriteCallback callback)
+    {
+        send(new Message(text, null, callback));
+    }
+
+    /**
+     * <p>Sends a binary message, or buffers it if the connection is not open.</p>
+     *
+     * @param bytes the binary message
+     * @param callback the callback completed when the message is sent, or fails to be sent or buffered
+     */
+    public void sendBytes(ByteBuffer bytes, WriteCallback callback)
+    {
+        send(new Message(null, bytes, callback));
+    }
+
+    /**
+     * <p>Closes the connection normally and stops reconnecting.</p>
+     * <p>Buffered messages are failed.</p>
+     */
+    public void close()
+    {
+        close(StatusCode.NORMAL, null);
+    }
+
+    /**
+     * <p>Closes the connection with the given status code and stops reconnecting.</p>
+     * <p>Buffered messages are failed.</p>
+     *
+     * @param statusCode the close status code
+     * @param reason the close reason
+     */
+    public void close(int statusCode, String reason)
+    {
+        Session session;
+        try (AutoLock ignored = lock.lock())
+        {
+            session = this.session;
+            this.session = null;
+            current = null;
+        }
+        if (session != null)
+            session.close(statusCode, reason);
+        closed(null);
+    }
+
+    /**
+     * <p>Returns whether the connection should be re-established after it closed
+     * with the given status code.</p>
+     * <p>By default, the connection is re-established unless it was closed normally.</p>
+     *
+     * @param statusCode the close status code
+     * @param reason the close reason
+     * @return whether to reconnect
+     */
+    protected boolean shouldReconnect(int statusCode, String reason)
+    {
+        return statusCode != StatusCode.NORMAL;
+    }
+
+    /**
+     * @param attempt the reconnection attempt, starting from 1
+     * @return the delay in milliseconds before the given reconnection attempt
+     */
+    protected long getBackoff(int attempt)
+    {
+        double delay = initialBackoff * Math.pow(backoffMultiplier, attempt - 1);
+        long backoff = (long)Math.min(maxBackoff, delay);
+        // Equal jitter: half of the delay is fixed, the other half random.
+        long half = backoff / 2;
+        return half + (half > 0 ? ThreadLocalRandom.current().nextLong(half + 1) : 0);
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        close(StatusCode.SHUTDOWN, "Client Stopped");
+        super.doStop();
+    }
+
+    private void doConnect()
+    {
+        EndpointAdapter adapter = new EndpointAdapter();
+        try (AutoLock ignored = lock.lock())
+        {
+            if (state == State.CLOSED)
+                return;
+            reconnectTask = null;
+            current = adapter;
+        }
+        notifyStateChange(State.CONNECTING, null);
+        try
+        {
+            client.connect(adapter, uri, upgradeRequestSupplier.get()).whenComplete((session, failure) ->
+            {
+                if (failure != null)
+                    disconnected(adapter, failure);
+            });
+        }
+        catch (Throwable x)
+        {
+            disconnected(adapter, x);
+        }
+    }
+
+    private void connected(EndpointAdapter adapter, Session session)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            if (current != adapter)
+                return;
+            this.session = session;
+        }
+
+        try
+        {
+            if (resumeHandler != null)
+                resumeHandler.onResume(session, attempts);
+        }
+        catch (Throwable x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Resume failed for {}", session, x);
+            session.close(StatusCode.SERVER_ERROR, "Resume Failed");
+            return;
+        }
+
+        try (AutoLock ignored = lock.lock())
+        {
+            if (current != adapter)
+                return;
+            // Send the buffered messages while holding the lock,
+            // so that new messages cannot overtake them.
+            while (!messages.isEmpty())
+            {
+                Message message = messages.poll();
+                bufferedBytes -= message.size();
+                message.send(session);
+            }
+            attempts = 0;
+            state = State.OPEN;
+        }
+        notifyState(State.OPEN, null);
+    }
+
+    private void disconnected(EndpointAdapter adapter, int statusCode, String reason)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            if (current != adapter)
+                return;
+        }
+        if (shouldReconnect(statusCode, reason))
+            disconnected(adapter, new ClosedChannelException());
+        else
+            closed(null);
+    }
+
+    private void disconnected(EndpointAdapter adapter, Throwable failure)
+    {
+        long delay;
+        int attempt;
+        try (AutoLock ignored = lock.lock())
+        {
+            if (current != adapter || state == State.CLOSED)
+                return;
+            current = null;
+            session = null;
+            attempt = ++attempts;
+            if (maxReconnectAttempts >= 0 && attempt > maxReconnectAttempts)
+            {
+                delay = -1;
+            }
+            else
+            {
+                delay = getBackoff(attempt);
+                state = State.RECONNECTING;
+                reconnectTask = client.getHttpClient().getScheduler().schedule(this::doConnect, delay, TimeUnit.MILLISECONDS);
+            }
+        }
+
+        if (delay < 0)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Giving up reconnecting after {} attempts to {}", attempt - 1, uri, failure);
+            closed(failure);
+        }
+        else
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("Reconnecting attempt {} in {} ms to {}", attempt, delay, uri, failure);
+            notifyState(State.RECONNECTING, failure);
+        }
+    }
+
+    private void closed(Throwable failure)
+    {
+        List<Message> failed;
+        Scheduler.Task task;
+        try (AutoLock ignored = lock.lock())
+        {
+            if (state == State.CLOSED)
+                return;
+            state = State.CLOSED;
+            current = null;
+            task = reconnectTask;
+            reconnectTask = null;
+            failed = new ArrayList<>(messages);
+            messages.clear();
+            bufferedBytes = 0;
+        }
+        if (task != null)
+            task.cancel();
+        Throwable cause = failure != null ? failure : new ClosedChannelException();
+        failed.forEach(message -> message.fail(cause));
+        notifyState(State.CLOSED, failure);
+    }
+
+    private void send(Message message)
+    {
+        Throwable failure = null;
+        try (AutoLock ignored = lock.lock())
+        {
+            switch (state)
+            {
+                case OPEN:
+                    message.send(session);
+                    return;
+                case CLOSED:
+                    failure = new ClosedChannelException();
+                    break;
+                default:
+                    if (messages.size() >= maxBufferedMessages || bufferedBytes + message.size() > maxBufferedBytes)
+                    {
+                        failure = new IllegalStateException("Message buffer full");
+                    }
+                    else
+                    {
+                        messages.offer(message);
+                        bufferedBytes += message.size();
+                    }
+                    break;
+            }
+        }
+        if (failure != null)
+            message.fail(failure);
+    }
+
+    private void notifyStateChange(State state, Throwable failure)
+    {
+        try (AutoLock ignored = lock.lock())
+        {
+            if (this.state == State.CLOSED)
+                return;
+            this.state = state;
+        }
+        notifyState(state, failure);
+    }
+
+    private void notifyState(State state, Throwable failure)
+    {
+        for (Listener listener : listeners)
+        {
+            try
+            {
+                listener.onStateChange(this, state, failure);
+            }
+            catch (Throwable x)
+            {
+                LOG.info("Failure while notifying listener {}", listener, x);
+            }
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x[%s,%s]", getClass().getSimpleName(), hashCode(), uri, getState());
+    }
+
+    /**
+     * <p>The state of a {@link ReconnectingWebSocketClient}.</p>
+     */
+    public enum State
+    {
+        /**
+         * Not yet connected.
+         */
+        IDLE,
+        /**
+         * A connection attempt is in progress.
+         */
+        CONNECTING,
+        /**
+         * The connection is open.
+         */
+        OPEN,
+        /**
+         * The connection is closed and a reconnection attempt is scheduled.
+         */
+        RECONNECTING,
+        /**
+         * The connection has been closed and will not be re-established.
+         */
+        CLOSED
+    }
+
+    /**
+     * <p>Listener for state changes of a {@link ReconnectingWebSocketClient}.</p>
+     */
+    public interface Listener extends EventListener
+    {
+        /**
+         * <p>Callback method invoked when the state changes.</p>
+         *
+         * @param client the client whose state changed
+         * @param state the new state
+         * @param failure the failure that caused the state change, or {@code null}
+         */
+        void onStateChange(ReconnectingWebSocketClient client, State state, Throwable failure);
+    }
+
+    /**
+     * <p>Handler called for each new connection, before buffered messages are sent.</p>
+     */
+    @FunctionalInterface
+    public interface ResumeHandler
+    {
+        /**
+         * <p>Restores the application state on a new connection, for example
+         * by sending messages directly via {@link Session#getRemote()}.</p>
+         * <p>If this method throws, the connection is closed and re-established.</p>
+         *
+         * @param session the new session
+         * @param attempt 0 for the first connection, otherwise the number of the reconnection attempt
+         * @throws Exception if the application state cannot be restored
+         */
+        void onResume(Session session, int attempt) throws Exception;
+    }
+
+    private class EndpointAdapter implements WebSocketListener
+    {
+        @Override
+        public void onWebSocketConnect(Session session)
+        {
+            endpoint.onWebSocketConnect(session);
+            connected(this, session);
+        }
+
+        @Override
+        public void onWebSocketText(String message)
+        {
+            if (endpoint instanceof WebSocketListener)
+                ((WebSocketListener)endpoint).onWebSocketText(message);
+        }
+
+        @Override
+        public void onWebSocketBinary(byte[] payload, int offset, int len)
+        {
+            if (endpoint instanceof WebSocketListener)
+                ((WebSocketListener)endpoint).onWebSocketBinary(payload, offset, len);
+        }
+
+        @Override
+        public void onWebSocketError(Throwable cause)
+        {
+            endpoint.onWebSocketError(cause);
+        }
+
+        @Override
+        public void onWebSocketClose(int statusCode, String reason)
+        {
+            endpoint.onWebSocketClose(statusCode, reason);
+            disconnected(this, statusCode, reason);
+        }
+    }
+
+    private static class Message
+    {
+        private final String text;
+        private final ByteBuffer bytes;
+        private final WriteCallback callback;
+
+        private Message(String text, ByteBuffer bytes, WriteCallback callback)
+        {
+            this.text = text;
+            this.bytes = bytes;
+            this.callback = callback == null ? WriteCallback.NOOP : callback;
+        }
+
+        private long size()
+        {
+            return text != null ? text.length() : bytes.remaining();
+        }
+
+        private void send(Session session)
+        {
+            if (text != null)
+                session.getRemote().sendString(text, callback);
+            else
+                session.getRemote().sendBytes(bytes, callback);
+        }
+
+        private void fail(Throwable failure)
+        {
+            callback.writeFailed(failure);
+        }
+    }
+}
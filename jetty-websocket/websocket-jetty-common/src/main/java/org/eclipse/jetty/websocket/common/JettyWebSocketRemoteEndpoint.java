@@ -14,13 +14,19 @@
 package org.eclipse.jetty.websocket.common;
 
 import java.io.IOException;
+import java.io.InputStream;
 import java.net.SocketAddress;
 import java.nio.ByteBuffer;
+import java.util.ArrayList;
+import java.util.List;
 import java.util.Objects;
 
 import org.eclipse.jetty.util.BufferUtil;
 import org.eclipse.jetty.util.Callback;
 import org.eclipse.jetty.util.FutureCallback;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.IteratingCallback;
+import org.eclipse.jetty.util.thread.AutoLock;
 import org.eclipse.jetty.websocket.api.BatchMode;
 import org.eclipse.jetty.websocket.api.WriteCallback;
 import org.eclipse.jetty.websocket.core.CoreSession;
@@ -36,9 +42,16 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
 {
     private static final Logger LOG = LoggerFactory.getLogger(JettyWebSocketRemoteEndpoint.class);
 
+    private final AutoLock lock = new AutoLock();
+    private final List<Runnable> drainedActions = new ArrayList<>();
     private final CoreSession coreSession;
     private byte messageType = -1;
     private BatchMode batchMode;
+    private long outgoingBytes;
+    private long lowWatermark;
+    private long highWatermark;
+    private WatermarkListener watermarkListener;
+    private boolean congested;
 
     public JettyWebSocketRemoteEndpoint(CoreSession coreSession, BatchMode batchMode)
     {
@@ -56,7 +69,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
     public void sendString(String text, WriteCallback callback)
     {
         Callback cb = callback == null ? Callback.NOOP : Callback.from(callback::writeSuccess, callback::writeFailed);
-        coreSession.sendFrame(new Frame(OpCode.TEXT).setPayload(text), cb, isBatch());
+        sendFrame(new Frame(OpCode.TEXT).setPayload(text), cb, isBatch());
     }
 
     @Override
@@ -68,7 +81,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
     @Override
     public void sendBytes(ByteBuffer data, WriteCallback callback)
     {
-        coreSession.sendFrame(new Frame(OpCode.BINARY).setPayload(data),
+        sendFrame(new Frame(OpCode.BINARY).setPayload(data),
             Callback.from(callback::writeSuccess, callback::writeFailed),
             isBatch());
     }
@@ -107,7 +120,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
         frame.setPayload(fragment);
         frame.setFin(isLast);
 
-        coreSession.sendFrame(frame, callback, isBatch());
+        sendFrame(frame, callback, isBatch());
 
         if (isLast)
         {
@@ -139,7 +152,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
     @Override
     public void sendPing(ByteBuffer applicationData, WriteCallback callback)
     {
-        coreSession.sendFrame(new Frame(OpCode.PING).setPayload(applicationData),
+        sendFrame(new Frame(OpCode.PING).setPayload(applicationData),
             Callback.from(callback::writeSuccess, callback::writeFailed), false);
     }
 
@@ -152,7 +165,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
     @Override
     public void sendPong(ByteBuffer applicationData, WriteCallback callback)
     {
-        coreSession.sendFrame(new Frame(OpCode.PONG).setPayload(applicationData),
+        sendFrame(new Frame(OpCode.PONG).setPayload(applicationData),
             Callback.from(callback::writeSuccess, callback::writeFailed), false);
     }
 
@@ -176,7 +189,7 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
         frame.setPayload(BufferUtil.toBuffer(fragment, UTF_8));
         frame.setFin(isLast);
 
-        coreSession.sendFrame(frame, callback, isBatch());
+        sendFrame(frame, callback, isBatch());
 
         if (isLast)
         {
@@ -184,10 +197,189 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
         }
     }
 
+    @Override
+    public void sendStream(InputStream stream) throws IOException
+    {
+        try (InputStream input = stream)
+        {
+            if (messageType != -1)
+                throw new ProtocolException("Attempt to send Stream during active opcode " + messageType);
+            messageType = OpCode.BINARY;
+            byte[] buffer = new byte[getFragmentSize()];
+            boolean first = true;
+            boolean last = false;
+            while (!last)
+            {
+                FutureCallback drained = new FutureCallback();
+                if (awaitDrained(drained::succeeded))
+                    drained.block();
+                int length = fill(input, buffer);
+                last = length < buffer.length;
+                Frame frame = new Frame(first ? OpCode.BINARY : OpCode.CONTINUATION);
+                frame.setPayload(ByteBuffer.wrap(buffer, 0, length));
+                frame.setFin(last);
+                first = false;
+                FutureCallback b = new FutureCallback();
+                sendFrame(frame, b, isBatch());
+                b.block();
+            }
+        }
+        finally
+        {
+            messageType = -1;
+        }
+    }
+
+    @Override
+    public void sendStream(InputStream stream, WriteCallback callback)
+    {
+        Callback cb = callback == null ? Callback.NOOP : Callback.from(callback::writeSuccess, callback::writeFailed);
+        if (messageType != -1)
+        {
+            IO.close(stream);
+            cb.failed(new ProtocolException("Attempt to send Stream during active opcode " + messageType));
+            return;
+        }
+        messageType = OpCode.BINARY;
+        new StreamSender(stream, getFragmentSize(), cb).iterate();
+    }
+
+    private int getFragmentSize()
+    {
+        int fragmentSize = coreSession.getOutputBufferSize();
+        long maxFrameSize = coreSession.getMaxFrameSize();
+        if (maxFrameSize > 0)
+            fragmentSize = (int)Math.min(fragmentSize, maxFrameSize);
+        return Math.max(1, fragmentSize);
+    }
+
+    /**
+     * Reads the stream until the buffer is full or the stream is at EOF.
+     *
+     * @return the number of bytes read, less than the buffer length only at EOF
+     */
+    private static int fill(InputStream stream, byte[] buffer) throws IOException
+    {
+        int length = 0;
+        while (length < buffer.length)
+        {
+            int read = stream.read(buffer, length, buffer.length - length);
+            if (read < 0)
+                break;
+            length += read;
+        }
+        return length;
+    }
+
+    private void sendFrame(Frame frame, Callback callback, boolean batch)
+    {
+        long length = frame.getPayloadLength();
+        acquire(length);
+        coreSession.sendFrame(frame, Callback.from(() -> release(length), callback), batch);
+    }
+
+    private void acquire(long length)
+    {
+        WatermarkListener listener = null;
+        try (AutoLock l = lock.lock())
+        {
+            outgoingBytes += length;
+            if (!congested && highWatermark > 0 && outgoingBytes > highWatermark)
+            {
+                congested = true;
+                listener = watermarkListener;
+            }
+        }
+        if (listener != null)
+            notifyHighWatermark(listener);
+    }
+
+    private void release(long length)
+    {
+        WatermarkListener listener = null;
+        List<Runnable> actions = null;
+        try (AutoLock l = lock.lock())
+        {
+            outgoingBytes -= length;
+            if (congested && outgoingBytes <= lowWatermark)
+            {
+                congested = false;
+                listener = watermarkListener;
+                actions = new ArrayList<>(drainedActions);
+                drainedActions.clear();
+            }
+        }
+        if (listener != null)
+            notifyLowWatermark(listener);
+        if (actions != null)
+            actions.forEach(Runnable::run);
+    }
+
+    /**
+     * @param action the action to run when the outgoing bytes drop to the low watermark
+     * @return true if the action will be run later, false if the outgoing bytes are below the high watermark
+     */
+    private boolean awaitDrained(Runnable action)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (!congested)
+                return false;
+            drainedActions.add(action);
+            return true;
+        }
+    }
+
+    private void notifyHighWatermark(WatermarkListener listener)
+    {
+        try
+        {
+            listener.onHighWatermark(this);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyLowWatermark(WatermarkListener listener)
+    {
+        try
+        {
+            listener.onLowWatermark(this);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("Failure while notifying listener {}", listener, x);
+        }
+    }
+
+    @Override
+    public long getOutgoingBytes()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return outgoingBytes;
+        }
+    }
+
+    @Override
+    public void setOutgoingWatermarks(long lowWatermark, long highWatermark, WatermarkListener listener)
+    {
+        if (highWatermark > 0 && (lowWatermark < 0 || lowWatermark > highWatermark))
+            throw new IllegalArgumentException("Invalid watermarks " + lowWatermark + "/" + highWatermark);
+        try (AutoLock l = lock.lock())
+        {
+            this.lowWatermark = lowWatermark;
+            this.highWatermark = highWatermark;
+            this.watermarkListener = listener;
+        }
+    }
+
     private void sendBlocking(Frame frame) throws IOException
     {
         FutureCallback b = new FutureCallback();
-        coreSession.sendFrame(frame, b, false);
+        sendFrame(frame, b, false);
         b.block();
     }
 
@@ -233,4 +425,57 @@ public class JettyWebSocketRemoteEndpoint implements org.eclipse.jetty.websocket
         coreSession.flush(b);
         b.block();
     }
+
+    private class StreamSender extends IteratingCallback
+    {
+        private final InputStream stream;
+        private final byte[] buffer;
+        private final Callback callback;
+        private boolean first = true;
+        private boolean last;
+
+        private StreamSender(InputStream stream, int fragmentSize, Callback callback)
+        {
+            this.stream = stream;
+            this.buffer = new byte[fragmentSize];
+            this.callback = callback;
+        }
+
+        @Override
+        protected Action process() throws Throwable
+        {
+            if (last)
+                return Action.SUCCEEDED;
+            // Resume sending only when the outgoing bytes are drained.
+            if (awaitDrained(this::iterate))
+                return Action.IDLE;
+
+            int length = fill(stream, buffer);
+            last = length < buffer.length;
+            Frame frame = new Frame(first ? OpCode.BINARY : OpCode.CONTINUATION);
+            frame.setPayload(ByteBuffer.wrap(buffer, 0, length));
+            frame.setFin(last);
+            first = false;
+            if (LOG.isDebugEnabled())
+                LOG.debug("Sending stream fragment {} on {}", frame, coreSession);
+            sendFrame(frame, this, isBatch());
+            return Action.SCHEDULED;
+        }
+
+        @Override
+        protected void onCompleteSuccess()
+        {
+            IO.close(stream);
+            messageType = -1;
+            callback.succeeded();
+        }
+
+        @Override
+        protected void onCompleteFailure(Throwable cause)
+        {
+            IO.close(stream);
+            messageType = -1;
+            callback.failed(cause);
+        }
+    }
 }
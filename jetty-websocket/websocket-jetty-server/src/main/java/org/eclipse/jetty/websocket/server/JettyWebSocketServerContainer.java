@@ -36,6 +36,7 @@ import org.eclipse.jetty.websocket.api.WebSocketPolicy;
 import org.eclipse.jetty.websocket.api.WebSocketSessionListener;
 import org.eclipse.jetty.websocket.common.SessionTracker;
 import org.eclipse.jetty.websocket.core.Configuration;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
 import org.eclipse.jetty.websocket.core.exception.WebSocketException;
 import org.eclipse.jetty.websocket.core.internal.util.ReflectUtils;
@@ -167,6 +168,18 @@ public class JettyWebSocketServerContainer extends ContainerLifeCycle implements
         });
     }
 
+    /**
+     * <p>Adds a {@link MessageInterceptor} to the messages of the endpoints of the given mapping.</p>
+     *
+     * @param pathSpec the pathspec of the mapping
+     * @param interceptor the interceptor, applied after the interceptors already added
+     * @see WebSocketMappings#addMessageInterceptor(PathSpec, MessageInterceptor)
+     */
+    public void addMessageInterceptor(String pathSpec, MessageInterceptor interceptor)
+    {
+        webSocketMappings.addMessageInterceptor(WebSocketMappings.parsePathSpec(pathSpec), interceptor);
+    }
+
     /**
      * An immediate programmatic WebSocket upgrade that does not register a mapping or create a {@link WebSocketUpgradeFilter}.
      * @param creator the WebSocketCreator to use.
@@ -25,6 +25,7 @@ import javax.servlet.http.HttpServletResponse;
 
 import org.eclipse.jetty.server.handler.ContextHandler;
 import org.eclipse.jetty.websocket.core.Configuration;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 import org.eclipse.jetty.websocket.core.WebSocketComponents;
 import org.eclipse.jetty.websocket.core.server.FrameHandlerFactory;
 import org.eclipse.jetty.websocket.core.server.ServerUpgradeRequest;
@@ -205,6 +206,12 @@ public abstract class JettyWebSocketServlet extends HttpServlet
             mapping.addMapping(WebSocketMappings.parsePathSpec(pathSpec), new WrappedJettyCreator(creator), getFactory(), this);
         }
 
+        @Override
+        public void addMessageInterceptor(String pathSpec, MessageInterceptor interceptor)
+        {
+            mapping.addMessageInterceptor(WebSocketMappings.parsePathSpec(pathSpec), interceptor);
+        }
+
         @Override
         public void register(Class<?> endpointClass)
         {
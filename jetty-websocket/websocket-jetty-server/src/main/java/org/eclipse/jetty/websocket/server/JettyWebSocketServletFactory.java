@@ -17,6 +17,7 @@ import java.util.Set;
 
 import org.eclipse.jetty.websocket.api.WebSocketBehavior;
 import org.eclipse.jetty.websocket.api.WebSocketPolicy;
+import org.eclipse.jetty.websocket.core.MessageInterceptor;
 
 public interface JettyWebSocketServletFactory extends WebSocketPolicy
 {
@@ -34,6 +35,14 @@ public interface JettyWebSocketServletFactory extends WebSocketPolicy
      */
     void addMapping(String pathSpec, JettyWebSocketCreator creator);
 
+    /**
+     * Add a {@link MessageInterceptor} to the messages of the endpoints of a mapping.
+     *
+     * @param pathSpec the pathspec of the mapping
+     * @param interceptor the interceptor, applied after the interceptors already added
+     */
+    void addMessageInterceptor(String pathSpec, MessageInterceptor interceptor);
+
     /**
      * Add a WebSocket mapping at PathSpec "/" for a creator which creates the endpointClass
      *
@@ -0,0 +1,159 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.tests;
+
+import java.io.ByteArrayInputStream;
+import java.io.ByteArrayOutputStream;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.websocket.api.RemoteEndpoint;
+import org.eclipse.jetty.websocket.api.WebSocketPartialListener;
+import org.eclipse.jetty.websocket.client.WebSocketClient;
+import org.eclipse.jetty.websocket.server.config.JettyWebSocketServletContainerInitializer;
+import org.eclipse.jetty.websocket.tests.util.FutureWriteCallback;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertArrayEquals;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class StreamSendTest
+{
+    private final PartialSocket serverSocket = new PartialSocket();
+    private Server server;
+    private ServerConnector connector;
+    private WebSocketClient client;
+
+    @BeforeEach
+    public void start() throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+
+        ServletContextHandler contextHandler = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        contextHandler.setContextPath("/");
+        JettyWebSocketServletContainerInitializer.configure(contextHandler, (context, container) ->
+            container.addMapping("/", (req, resp) -> serverSocket));
+        server.setHandler(contextHandler);
+
+        client = new WebSocketClient();
+        client.setOutputBufferSize(1024);
+        server.start();
+        client.start();
+    }
+
+    @AfterEach
+    public void stop() throws Exception
+    {
+        client.stop();
+        server.stop();
+    }
+
+    private RemoteEndpoint connect() throws Exception
+    {
+        URI uri = URI.create("ws://localhost:" + connector.getLocalPort() + "/");
+        EventSocket socket = new EventSocket();
+        client.connect(socket, uri).get(5, TimeUnit.SECONDS);
+        assertTrue(socket.openLatch.await(5, TimeUnit.SECONDS));
+        return socket.session.getRemote();
+    }
+
+    private static byte[] randomBytes(int length)
+    {
+        byte[] bytes = new byte[length];
+        ThreadLocalRandom.current().nextBytes(bytes);
+        return bytes;
+    }
+
+    @Test
+    public void testBlockingSendStream() throws Exception
+    {
+        RemoteEndpoint remote = connect();
+        byte[] content = randomBytes(50 * 1024);
+
+        remote.sendStream(new ByteArrayInputStream(content));
+
+        assertTrue(serverSocket.messageLatch.await(5, TimeUnit.SECONDS));
+        assertArrayEquals(content, serverSocket.message.toByteArray());
+        assertThat(serverSocket.fragments.get(), greaterThan(1));
+        assertThat(remote.getOutgoingBytes(), is(0L));
+    }
+
+    @Test
+    public void testAsyncSendStreamWithWatermarks() throws Exception
+    {
+        RemoteEndpoint remote = connect();
+        AtomicInteger highWatermarks = new AtomicInteger();
+        AtomicInteger lowWatermarks = new AtomicInteger();
+        // Every fragment exceeds the high watermark.
+        remote.setOutgoingWatermarks(0, 1, new RemoteEndpoint.WatermarkListener()
+        {
+            @Override
+            public void onHighWatermark(RemoteEndpoint endpoint)
+            {
+                highWatermarks.incrementAndGet();
+            }
+
+            @Override
+            public void onLowWatermark(RemoteEndpoint endpoint)
+            {
+                lowWatermarks.incrementAndGet();
+            }
+        });
+        byte[] content = randomBytes(10 * 1024);
+
+        FutureWriteCallback callback = new FutureWriteCallback();
+        remote.sendStream(new ByteArrayInputStream(content), callback);
+        callback.get(5, TimeUnit.SECONDS);
+
+        assertTrue(serverSocket.messageLatch.await(5, TimeUnit.SECONDS));
+        assertArrayEquals(content, serverSocket.message.toByteArray());
+        assertThat(highWatermarks.get(), greaterThan(1));
+        assertThat(lowWatermarks.get(), is(highWatermarks.get()));
+        assertThat(remote.getOutgoingBytes(), is(0L));
+
+        // Other messages can be sent after the stream.
+        remote.sendString("done");
+    }
+
+    public static class PartialSocket implements WebSocketPartialListener
+    {
+        private final ByteArrayOutputStream message = new ByteArrayOutputStream();
+        private final AtomicInteger fragments = new AtomicInteger();
+        private final CountDownLatch messageLatch = new CountDownLatch(1);
+
+        @Override
+        public void onWebSocketPartialBinary(ByteBuffer payload, boolean fin)
+        {
+            fragments.incrementAndGet();
+            message.writeBytes(BufferUtil.toArray(payload));
+            if (fin)
+                messageLatch.countDown();
+        }
+    }
+}
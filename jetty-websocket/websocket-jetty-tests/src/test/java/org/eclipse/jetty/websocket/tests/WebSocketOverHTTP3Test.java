@@ -0,0 +1,217 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.tests;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.net.InetSocketAddress;
+import java.net.URI;
+import java.security.KeyStore;
+import java.util.Map;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+import javax.servlet.http.HttpServlet;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.AbstractConnectionPool;
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpDestination;
+import org.eclipse.jetty.client.api.ContentResponse;
+import org.eclipse.jetty.client.dynamic.HttpClientTransportDynamic;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http3.api.Session;
+import org.eclipse.jetty.http3.client.HTTP3Client;
+import org.eclipse.jetty.http3.client.http.ClientConnectionFactoryOverHTTP3;
+import org.eclipse.jetty.http3.frames.SettingsFrame;
+import org.eclipse.jetty.http3.server.HTTP3ServerConnectionFactory;
+import org.eclipse.jetty.http3.server.HTTP3ServerConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.servlet.ServletHolder;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.eclipse.jetty.websocket.api.StatusCode;
+import org.eclipse.jetty.websocket.client.WebSocketClient;
+import org.eclipse.jetty.websocket.server.JettyWebSocketServlet;
+import org.eclipse.jetty.websocket.server.JettyWebSocketServletFactory;
+import org.eclipse.jetty.websocket.server.config.JettyWebSocketServletContainerInitializer;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
+import static org.junit.jupiter.api.Assertions.assertNull;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class WebSocketOverHTTP3Test
+{
+    public WorkDir workDir;
+    private Server server;
+    private HTTP3ServerConnector connector;
+    private HTTP3Client http3Client;
+    private HttpClient httpClient;
+    private WebSocketClient wsClient;
+
+    private void startServer() throws Exception
+    {
+        QueuedThreadPool serverThreads = new QueuedThreadPool();
+        serverThreads.setName("server");
+        server = new Server(serverThreads);
+
+        SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStorePath("src/test/resources/keystore.p12");
+        sslContextFactory.setKeyStorePassword("storepwd");
+        connector = new HTTP3ServerConnector(server, sslContextFactory, new HTTP3ServerConnectionFactory());
+        connector.getQuicConfiguration().setPemWorkDirectory(workDir.getEmptyPathDir());
+        server.addConnector(connector);
+
+        ServletContextHandler context = new ServletContextHandler(server, "/");
+        context.addServlet(new ServletHolder(new EchoWebSocketServlet()), "/ws/*");
+        context.addServlet(new ServletHolder(new HttpServlet()
+        {
+            @Override
+            protected void doGet(HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                response.setContentType("text/plain");
+                response.getWriter().print(request.getProtocol());
+            }
+        }), "/http");
+        JettyWebSocketServletContainerInitializer.configure(context, null);
+
+        server.start();
+    }
+
+    private void startClient() throws Exception
+    {
+        KeyStore trustStore = KeyStore.getInstance("PKCS12");
+        try (InputStream is = getClass().getResourceAsStream("/keystore.p12"))
+        {
+            trustStore.load(is, "storepwd".toCharArray());
+        }
+
+        http3Client = new HTTP3Client();
+        SslContextFactory.Client clientSslContextFactory = new SslContextFactory.Client();
+        clientSslContextFactory.setTrustStore(trustStore);
+        http3Client.getClientConnector().setSslContextFactory(clientSslContextFactory);
+        httpClient = new HttpClient(new HttpClientTransportDynamic(new ClientConnectionFactoryOverHTTP3.HTTP3(http3Client)));
+        QueuedThreadPool clientThreads = new QueuedThreadPool();
+        clientThreads.setName("client");
+        httpClient.setExecutor(clientThreads);
+        wsClient = new WebSocketClient(httpClient);
+        wsClient.start();
+    }
+
+    @AfterEach
+    public void dispose()
+    {
+        LifeCycle.stop(wsClient);
+        LifeCycle.stop(httpClient);
+        LifeCycle.stop(http3Client);
+        LifeCycle.stop(server);
+    }
+
+    @Test
+    public void testConnectProtocolAdvertised() throws Exception
+    {
+        startServer();
+        startClient();
+
+        CompletableFuture<Map<Long, Long>> settingsPromise = new CompletableFuture<>();
+        InetSocketAddress address = new InetSocketAddress("localhost", connector.getLocalPort());
+        http3Client.connect(address, new Session.Client.Listener()
+        {
+            @Override
+            public void onSettings(Session session, SettingsFrame frame)
+            {
+                settingsPromise.complete(frame.getSettings());
+            }
+        }).get(5, TimeUnit.SECONDS);
+
+        Map<Long, Long> settings = settingsPromise.get(5, TimeUnit.SECONDS);
+        assertThat(settings.get(SettingsFrame.ENABLE_CONNECT_PROTOCOL), is(1L));
+    }
+
+    @Test
+    public void testWebSocketAndHTTPRequestsOnSameConnection() throws Exception
+    {
+        startServer();
+        startClient();
+
+        EventSocket wsEndPoint = new EventSocket();
+        URI uri = URI.create("wss://localhost:" + connector.getLocalPort() + "/ws/echo");
+        org.eclipse.jetty.websocket.api.Session session = wsClient.connect(wsEndPoint, uri).get(5, TimeUnit.SECONDS);
+
+        // Interleave WebSocket messages and HTTP/3 requests.
+        for (int i = 0; i < 5; ++i)
+        {
+            String text = "websocket_" + i;
+            session.getRemote().sendString(text);
+
+            ContentResponse response = httpClient.GET("https://localhost:" + connector.getLocalPort() + "/http");
+            assertEquals(HttpStatus.OK_200, response.getStatus());
+            assertEquals("HTTP/3.0", response.getContentAsString());
+
+            String message = wsEndPoint.textMessages.poll(5, TimeUnit.SECONDS);
+            assertNotNull(message);
+            assertEquals(text, message);
+        }
+
+        // Both the WebSocket and the HTTP requests use the same QUIC connection.
+        HttpDestination destination = (HttpDestination)httpClient.getDestinations().get(0);
+        AbstractConnectionPool connectionPool = (AbstractConnectionPool)destination.getConnectionPool();
+        assertEquals(1, httpClient.getDestinations().size());
+        assertEquals(1, connectionPool.getConnectionCount());
+
+        session.close(StatusCode.NORMAL, null);
+        assertTrue(wsEndPoint.closeLatch.await(5, TimeUnit.SECONDS));
+        assertEquals(StatusCode.NORMAL, wsEndPoint.closeCode);
+        assertNull(wsEndPoint.error);
+
+        // The connection is still usable after the WebSocket is closed.
+        ContentResponse response = httpClient.GET("https://localhost:" + connector.getLocalPort() + "/http");
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+    }
+
+    @Test
+    public void testConnectProtocolDisabled() throws Exception
+    {
+        startServer();
+        connector.getConnectionFactory(HTTP3ServerConnectionFactory.class).getHTTP3Configuration().setConnectProtocolEnabled(false);
+        startClient();
+
+        EventSocket wsEndPoint = new EventSocket();
+        URI uri = URI.create("wss://localhost:" + connector.getLocalPort() + "/ws/echo");
+
+        assertThrows(ExecutionException.class, () -> wsClient.connect(wsEndPoint, uri).get(5, TimeUnit.SECONDS));
+    }
+
+    private static class EchoWebSocketServlet extends JettyWebSocketServlet
+    {
+        @Override
+        protected void configure(JettyWebSocketServletFactory factory)
+        {
+            factory.addMapping("/ws/echo", (request, response) -> new EchoSocket());
+        }
+    }
+}
@@ -0,0 +1,233 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.websocket.tests.client;
+
+import java.net.URI;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.eclipse.jetty.websocket.api.Session;
+import org.eclipse.jetty.websocket.api.StatusCode;
+import org.eclipse.jetty.websocket.api.WebSocketListener;
+import org.eclipse.jetty.websocket.api.WriteCallback;
+import org.eclipse.jetty.websocket.client.ClientUpgradeRequest;
+import org.eclipse.jetty.websocket.client.ReconnectingWebSocketClient;
+import org.eclipse.jetty.websocket.client.WebSocketClient;
+import org.eclipse.jetty.websocket.server.config.JettyWebSocketServletContainerInitializer;
+import org.eclipse.jetty.websocket.tests.EchoSocket;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class ReconnectingWebSocketClientTest
+{
+    private final BlockingQueue<EchoSocket> serverSockets = new LinkedBlockingQueue<>();
+    private final BlockingQueue<String> resumeTokens = new LinkedBlockingQueue<>();
+    private Server server;
+    private ServerConnector connector;
+    private WebSocketClient client;
+    private ReconnectingWebSocketClient reconnecting;
+
+    @BeforeEach
+    public void start() throws Exception
+    {
+        server = new Server();
+        connector = new ServerConnector(server);
+        server.addConnector(connector);
+
+        ServletContextHandler context = new ServletContextHandler();
+        context.setContextPath("/");
+        server.setHandler(context);
+
+        JettyWebSocketServletContainerInitializer.configure(context, (servletContext, wsContainer) ->
+            wsContainer.addMapping("/ws", (req, resp) ->
+            {
+                String token = req.getHeader("X-Resume-Token");
+                if (token != null)
+                    resumeTokens.offer(token);
+                EchoSocket socket = new EchoSocket();
+                serverSockets.offer(socket);
+                return socket;
+            }));
+        server.start();
+
+        client = new WebSocketClient();
+    }
+
+    @AfterEach
+    public void stop() throws Exception
+    {
+        if (reconnecting != null)
+            reconnecting.stop();
+        server.stop();
+    }
+
+    private URI newURI()
+    {
+        return URI.create("ws://localhost:" + connector.getLocalPort() + "/ws");
+    }
+
+    @Test
+    public void testReconnectOnAbnormalClose() throws Exception
+    {
+        ClientEndpoint endpoint = new ClientEndpoint();
+        reconnecting = new ReconnectingWebSocketClient(client, endpoint, newURI());
+        reconnecting.setInitialBackoff(500);
+        AtomicInteger connections = new AtomicInteger();
+        reconnecting.setUpgradeRequestSupplier(() ->
+        {
+            ClientUpgradeRequest request = new ClientUpgradeRequest();
+            request.setHeader("X-Resume-Token", "token-" + connections.getAndIncrement());
+            return request;
+        });
+        reconnecting.setResumeHandler((session, attempt) -> session.getRemote().sendString("resume-" + attempt));
+        BlockingQueue<ReconnectingWebSocketClient.State> states = new LinkedBlockingQueue<>();
+        reconnecting.addListener((c, state, failure) -> states.offer(state));
+
+        reconnecting.connect().get(5, TimeUnit.SECONDS);
+        assertThat(states.poll(5, TimeUnit.SECONDS), is(ReconnectingWebSocketClient.State.CONNECTING));
+        assertThat(states.poll(5, TimeUnit.SECONDS), is(ReconnectingWebSocketClient.State.OPEN));
+        assertThat(resumeTokens.poll(5, TimeUnit.SECONDS), is("token-0"));
+        assertThat(endpoint.messages.poll(5, TimeUnit.SECONDS), is("resume-0"));
+
+        // The server closes the connection abnormally.
+        EchoSocket serverSocket = serverSockets.poll(5, TimeUnit.SECONDS);
+        assertTrue(serverSocket.openLatch.await(5, TimeUnit.SECONDS));
+        serverSocket.session.close(StatusCode.SERVER_ERROR, "Test");
+        assertThat(states.poll(5, TimeUnit.SECONDS), is(ReconnectingWebSocketClient.State.RECONNECTING));
+
+        // Messages sent while disconnected are buffered.
+        CountDownLatch sentLatch = new CountDownLatch(1);
+        reconnecting.sendString("buffered", new WriteCallback()
+        {
+            @Override
+            public void writeSuccess()
+            {
+                sentLatch.countDown();
+            }
+        });
+        assertThat(reconnecting.getBufferedMessages(), is(1));
+
+        assertThat(states.poll(5, TimeUnit.SECONDS), is(ReconnectingWebSocketClient.State.CONNECTING));
+        assertThat(states.poll(5, TimeUnit.SECONDS), is(ReconnectingWebSocketClient.State.OPEN));
+        assertThat(resumeTokens.poll(5, TimeUnit.SECONDS), is("token-1"));
+        // The resume message is sent before the buffered messages.
+        assertThat(endpoint.messages.poll(5, TimeUnit.SECONDS), is("resume-1"));
+        assertThat(endpoint.messages.poll(5, TimeUnit.SECONDS), is("buffered"));
+        assertTrue(sentLatch.await(5, TimeUnit.SECONDS));
+        assertThat(endpoint.connects.get(), is(2));
+    }
+
+    @Test
+    public void testNoReconnectOnNormalClose() throws Exception
+    {
+        ClientEndpoint endpoint = new ClientEndpoint();
+        reconnecting = new ReconnectingWebSocketClient(client, endpoint, newURI());
+        reconnecting.connect().get(5, TimeUnit.SECONDS);
+
+        EchoSocket serverSocket = serverSockets.poll(5, TimeUnit.SECONDS);
+        assertTrue(serverSocket.openLatch.await(5, TimeUnit.SECONDS));
+        serverSocket.session.close(StatusCode.NORMAL, "Bye");
+
+        assertTrue(endpoint.closeLatch.await(5, TimeUnit.SECONDS));
+        assertThat(reconnecting.getState(), is(ReconnectingWebSocketClient.State.CLOSED));
+
+        CompletableFuture<Throwable> failure = new CompletableFuture<>();
+        reconnecting.sendString("after close", new WriteCallback()
+        {
+            @Override
+            public void writeFailed(Throwable x)
+            {
+                failure.complete(x);
+            }
+        });
+        assertThat(failure.get(5, TimeUnit.SECONDS), instanceOf(Exception.class));
+    }
+
+    @Test
+    public void testGiveUpAfterMaxReconnectAttempts() throws Exception
+    {
+        URI uri = newURI();
+        server.stop();
+
+        reconnecting = new ReconnectingWebSocketClient(client, new ClientEndpoint(), uri);
+        reconnecting.setInitialBackoff(200);
+        reconnecting.setMaxReconnectAttempts(2);
+        reconnecting.setMaxBufferedMessages(1);
+        AtomicInteger attempts = new AtomicInteger();
+        reconnecting.addListener((c, state, failure) ->
+        {
+            if (state == ReconnectingWebSocketClient.State.CONNECTING)
+                attempts.incrementAndGet();
+        });
+
+        CompletableFuture<Session> connect = reconnecting.connect();
+
+        CompletableFuture<Throwable> overflow = new CompletableFuture<>();
+        reconnecting.sendString("one", WriteCallback.NOOP);
+        reconnecting.sendString("two", new WriteCallback()
+        {
+            @Override
+            public void writeFailed(Throwable x)
+            {
+                overflow.complete(x);
+            }
+        });
+        assertThat(overflow.get(5, TimeUnit.SECONDS), instanceOf(IllegalStateException.class));
+
+        CompletableFuture<Session> done = connect.handle((session, failure) -> session);
+        done.get(5, TimeUnit.SECONDS);
+        assertTrue(connect.isCompletedExceptionally());
+        assertThat(reconnecting.getState(), is(ReconnectingWebSocketClient.State.CLOSED));
+        // The first connection attempt and 2 reconnection attempts.
+        assertThat(attempts.get(), is(3));
+    }
+
+    public static class ClientEndpoint implements WebSocketListener
+    {
+        public final BlockingQueue<String> messages = new LinkedBlockingQueue<>();
+        public final AtomicInteger connects = new AtomicInteger();
+        public final CountDownLatch closeLatch = new CountDownLatch(1);
+
+        @Override
+        public void onWebSocketConnect(Session session)
+        {
+            connects.incrementAndGet();
+        }
+
+        @Override
+        public void onWebSocketText(String message)
+        {
+            messages.offer(message);
+        }
+
+        @Override
+        public void onWebSocketClose(int statusCode, String reason)
+        {
+            closeLatch.countDown();
+        }
+    }
+}
@@ -0,0 +1,325 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.client;
+
+import java.io.IOException;
+import java.net.InetSocketAddress;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.util.concurrent.CompletableFuture;
+
+import org.eclipse.jetty.http.HostPortHttpField;
+import org.eclipse.jetty.http.HttpFields;
+import org.eclipse.jetty.http.HttpScheme;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http3.HTTP3Configuration;
+import org.eclipse.jetty.http3.api.Session;
+import org.eclipse.jetty.http3.api.Stream;
+import org.eclipse.jetty.http3.client.HTTP3Client;
+import org.eclipse.jetty.http3.frames.HeadersFrame;
+import org.eclipse.jetty.http3.frames.SettingsFrame;
+import org.eclipse.jetty.http3.internal.HTTP3Stream;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.component.ContainerLifeCycle;
+import org.eclipse.jetty.webtransport.api.WebTransportSession;
+import org.eclipse.jetty.webtransport.api.WebTransportStream;
+import org.eclipse.jetty.webtransport.internal.HTTP3WebTransportSession;
+import org.eclipse.jetty.webtransport.internal.WebTransportSessionConnection;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A client that establishes WebTransport sessions over HTTP/3.</p>
+ * <p>Each WebTransport session is established on its own HTTP/3 connection,
+ * that is closed when the WebTransport session is closed.</p>
+ * <pre>{@code
+ * WebTransportClient client = new WebTransportClient();
+ * client.start();
+ *
+ * WebTransportSession session = client.connect(URI.create("https://localhost:8443/wt"), new WebTransportSession.Listener()
+ * {
+ *     @Override
+ *     public void onDatagram(WebTransportSession session, ByteBuffer payload)
+ *     {
+ *         // Datagram received.
+ *     }
+ * }).get();
+ *
+ * WebTransportStream stream = session.newBidirectionalStream().get();
+ * }</pre>
+ */
+public class WebTransportClient extends ContainerLifeCycle
+{
+    private static final String PROTOCOL = "webtransport";
+    private static final Logger LOG = LoggerFactory.getLogger(WebTransportClient.class);
+
+    private final HTTP3Client http3Client;
+
+    public WebTransportClient()
+    {
+        this(new HTTP3Client());
+    }
+
+    public WebTransportClient(HTTP3Client http3Client)
+    {
+        this.http3Client = http3Client;
+        addBean(http3Client);
+        // WebTransport requires QUIC and HTTP datagrams.
+        http3Client.getQuicConfiguration().setDatagramsEnabled(true);
+        HTTP3Configuration configuration = http3Client.getHTTP3Configuration();
+        configuration.setDatagramsEnabled(true);
+        configuration.setWebTransportEnabled(true);
+    }
+
+    public HTTP3Client getHTTP3Client()
+    {
+        return http3Client;
+    }
+
+    public ClientConnector getClientConnector()
+    {
+        return http3Client.getClientConnector();
+    }
+
+    /**
+     * <p>Establishes a WebTransport session with the given URI.</p>
+     *
+     * @param uri the WebTransport URI, with the {@code https} scheme
+     * @param listener the listener of WebTransport session events
+     * @return a {@link CompletableFuture} completed when the session is established
+     * @see #connect(URI, HttpFields, WebTransportSession.Listener)
+     */
+    public CompletableFuture<WebTransportSession> connect(URI uri, WebTransportSession.Listener listener)
+    {
+        return connect(uri, HttpFields.EMPTY, listener);
+    }
+
+    /**
+     * <p>Establishes a WebTransport session with the given URI,
+     * sending the given headers with the CONNECT request.</p>
+     *
+     * @param uri the WebTransport URI, with the {@code https} scheme
+     * @param headers the headers of the CONNECT request, for example {@code Origin}
+     * @param listener the listener of WebTransport session events
+     * @return a {@link CompletableFuture} completed when the session is established
+     */
+    public CompletableFuture<WebTransportSession> connect(URI uri, HttpFields headers, WebTransportSession.Listener listener)
+    {
+        Promise.Completable<WebTransportSession> promise = new Promise.Completable<>();
+        if (!HttpScheme.HTTPS.is(uri.getScheme()))
+        {
+            promise.failed(new IllegalArgumentException("Invalid WebTransport URI scheme: " + uri));
+            return promise;
+        }
+
+        int port = HttpScheme.normalizePort(uri.getScheme(), uri.getPort());
+        InetSocketAddress address = new InetSocketAddress(uri.getHost(), port);
+        SessionListener sessionListener = new SessionListener();
+        http3Client.connect(address, sessionListener)
+            .thenCompose(session -> sessionListener.settings.thenApply(settings -> session))
+            .whenComplete((session, failure) ->
+            {
+                if (failure == null)
+                    newRequest(session, uri, port, headers, listener, promise);
+                else
+                    promise.failed(failure);
+            });
+        return promise;
+    }
+
+    private void newRequest(Session.Client session, URI uri, int port, HttpFields headers, WebTransportSession.Listener listener, Promise<WebTransportSession> promise)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("establishing WebTransport session to {} on {}", uri, session);
+
+        String path = uri.getRawPath();
+        if (path == null || path.isEmpty())
+            path = "/";
+        String query = uri.getRawQuery();
+        if (query != null)
+            path += "?" + query;
+        MetaData.ConnectRequest request = new MetaData.ConnectRequest(HttpScheme.HTTPS, new HostPortHttpField(uri.getHost(), port), path, headers, PROTOCOL);
+        StreamListener streamListener = new StreamListener(session, listener, promise);
+        session.newRequest(new HeadersFrame(request, false), streamListener)
+            .whenComplete((stream, failure) ->
+            {
+                if (failure == null)
+                {
+                    streamListener.onStream((HTTP3Stream)stream);
+                }
+                else
+                {
+                    session.goAway(false);
+                    promise.failed(failure);
+                }
+            });
+    }
+
+    private static class SessionListener implements Session.Client.Listener
+    {
+        private final CompletableFuture<Void> settings = new CompletableFuture<>();
+
+        @Override
+        public void onSettings(Session session, SettingsFrame frame)
+        {
+            Long value = frame.getSettings().get(SettingsFrame.ENABLE_WEBTRANSPORT);
+            if (value != null && value == 1)
+                settings.complete(null);
+            else
+                settings.completeExceptionally(new IOException("WebTransport not supported by " + session.getRemoteSocketAddress()));
+        }
+
+        @Override
+        public void onFailure(Session session, long error, String reason, Throwable failure)
+        {
+            settings.completeExceptionally(failure);
+        }
+    }
+
+    private class StreamListener implements Stream.Client.Listener
+    {
+        private final CompletableFuture<HTTP3WebTransportSession> sessionFuture = new CompletableFuture<>();
+        private final Session.Client http3Session;
+        private final WebTransportSession.Listener listener;
+        private final Promise<WebTransportSession> promise;
+
+        private StreamListener(Session.Client http3Session, WebTransportSession.Listener listener, Promise<WebTransportSession> promise)
+        {
+            this.http3Session = http3Session;
+            this.listener = listener;
+            this.promise = promise;
+        }
+
+        private void onStream(HTTP3Stream stream)
+        {
+            HTTP3StreamEndPoint tunnel = new HTTP3StreamEndPoint(stream);
+            // Register the WebTransport session now, before
+            // the response, to accept early streams.
+            sessionFuture.complete(new HTTP3WebTransportSession(tunnel, new ClientSessionListener(http3Session, listener)));
+        }
+
+        @Override
+        public void onResponse(Stream.Client stream, HeadersFrame frame)
+        {
+            sessionFuture.thenAccept(webTransportSession ->
+            {
+                MetaData.Response response = (MetaData.Response)frame.getMetaData();
+                int status = response.getStatus();
+                if (status == HttpStatus.OK_200)
+                {
+                    HTTP3StreamEndPoint tunnel = webTransportSession.getTunnel();
+                    WebTransportSessionConnection connection = new WebTransportSessionConnection(tunnel, http3Client.getClientConnector().getExecutor(), http3Client.getClientConnector().getByteBufferPool(), webTransportSession);
+                    if (LOG.isDebugEnabled())
+                        LOG.debug("established {}", webTransportSession);
+                    tunnel.upgrade(connection);
+                    promise.succeeded(webTransportSession);
+                }
+                else
+                {
+                    IOException failure = new IOException("WebTransport session rejected with status " + status);
+                    webTransportSession.abort(failure);
+                    http3Session.goAway(false);
+                    promise.failed(failure);
+                }
+            });
+        }
+
+        @Override
+        public void onDataAvailable(Stream.Client stream)
+        {
+            HTTP3WebTransportSession webTransportSession = sessionFuture.getNow(null);
+            if (webTransportSession != null)
+                webTransportSession.getTunnel().onDataAvailable().run();
+        }
+
+        @Override
+        public void onDatagram(Stream.Client stream, ByteBuffer payload)
+        {
+            HTTP3WebTransportSession webTransportSession = sessionFuture.getNow(null);
+            if (webTransportSession != null)
+                webTransportSession.onDatagram(payload);
+        }
+
+        @Override
+        public boolean onIdleTimeout(Stream.Client stream, Throwable failure)
+        {
+            HTTP3WebTransportSession webTransportSession = sessionFuture.getNow(null);
+            if (webTransportSession != null)
+                return webTransportSession.getTunnel().onIdleTimeout(failure);
+            return true;
+        }
+
+        @Override
+        public void onFailure(Stream.Client stream, long error, Throwable failure)
+        {
+            HTTP3WebTransportSession webTransportSession = sessionFuture.getNow(null);
+            if (webTransportSession != null)
+                webTransportSession.getTunnel().onFailure(failure);
+            promise.failed(failure);
+        }
+    }
+
+    private static class ClientSessionListener implements WebTransportSession.Listener
+    {
+        private final Session.Client http3Session;
+        private final WebTransportSession.Listener listener;
+
+        private ClientSessionListener(Session.Client http3Session, WebTransportSession.Listener listener)
+        {
+            this.http3Session = http3Session;
+            this.listener = listener;
+        }
+
+        @Override
+        public void onOpen(WebTransportSession session)
+        {
+            listener.onOpen(session);
+        }
+
+        @Override
+        public void onStream(WebTransportStream stream)
+        {
+            listener.onStream(stream);
+        }
+
+        @Override
+        public void onDatagram(WebTransportSession session, ByteBuffer payload)
+        {
+            listener.onDatagram(session, payload);
+        }
+
+        @Override
+        public void onClose(WebTransportSession session)
+        {
+            try
+            {
+                listener.onClose(session);
+            }
+            finally
+            {
+                // Each WebTransport session has its own HTTP/3 connection.
+                http3Session.goAway(true);
+            }
+        }
+
+        @Override
+        public void onFailure(WebTransportSession session, Throwable failure)
+        {
+            listener.onFailure(session, failure);
+        }
+    }
+}
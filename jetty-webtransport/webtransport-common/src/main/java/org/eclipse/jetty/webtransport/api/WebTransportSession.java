@@ -0,0 +1,140 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.api;
+
+import java.net.SocketAddress;
+import java.nio.ByteBuffer;
+import java.util.Collection;
+import java.util.concurrent.CompletableFuture;
+
+/**
+ * <p>A {@link WebTransportSession} represents a WebTransport session
+ * established over an HTTP/3 extended CONNECT request.</p>
+ * <p>A session multiplexes any number of bidirectional and unidirectional
+ * {@link WebTransportStream streams}, opened by either peer, and allows
+ * to exchange unreliable datagrams.</p>
+ * <p>A session is closed either explicitly via {@link #close()}, or when the
+ * CONNECT stream that established it is closed; when the session is closed,
+ * all its streams are reset.</p>
+ */
+public interface WebTransportSession
+{
+    /**
+     * @return the id of this session, that is the id of the CONNECT stream that established it
+     */
+    public long getId();
+
+    /**
+     * @return the local socket address of this session
+     */
+    public SocketAddress getLocalSocketAddress();
+
+    /**
+     * @return the remote socket address of this session
+     */
+    public SocketAddress getRemoteSocketAddress();
+
+    /**
+     * @return a snapshot of the streams currently opened in this session
+     */
+    public Collection<WebTransportStream> getStreams();
+
+    /**
+     * <p>Opens a new bidirectional stream.</p>
+     *
+     * @return a {@link CompletableFuture} completed when the stream has been opened
+     */
+    public CompletableFuture<WebTransportStream> newBidirectionalStream();
+
+    /**
+     * <p>Opens a new unidirectional, write-only, stream.</p>
+     *
+     * @return a {@link CompletableFuture} completed when the stream has been opened
+     */
+    public CompletableFuture<WebTransportStream> newUnidirectionalStream();
+
+    /**
+     * <p>Sends the given payload as an unreliable datagram.</p>
+     *
+     * @param payload the datagram payload
+     * @return a {@link CompletableFuture} completed when the datagram has been queued to be sent
+     */
+    public CompletableFuture<Void> sendDatagram(ByteBuffer payload);
+
+    /**
+     * @return whether this session is open
+     */
+    public boolean isOpen();
+
+    /**
+     * <p>Closes this session, resetting all its streams.</p>
+     */
+    public void close();
+
+    /**
+     * <p>A {@link Listener} is the passive counterpart of a {@link WebTransportSession}
+     * and receives events happening on a WebTransport session.</p>
+     */
+    public interface Listener
+    {
+        /**
+         * <p>Callback method invoked when the session has been established.</p>
+         *
+         * @param session the session
+         */
+        public default void onOpen(WebTransportSession session)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the remote peer opens a new stream.</p>
+         * <p>Applications should call {@link WebTransportStream#demand(Runnable)}
+         * on bidirectional and unidirectional streams to read their content.</p>
+         *
+         * @param stream the new stream
+         */
+        public default void onStream(WebTransportStream stream)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when a datagram is received.</p>
+         *
+         * @param session the session
+         * @param payload the datagram payload
+         */
+        public default void onDatagram(WebTransportSession session, ByteBuffer payload)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the session is closed.</p>
+         *
+         * @param session the session
+         */
+        public default void onClose(WebTransportSession session)
+        {
+        }
+
+        /**
+         * <p>Callback method invoked when the session failed.</p>
+         *
+         * @param session the session
+         * @param failure the failure
+         */
+        public default void onFailure(WebTransportSession session, Throwable failure)
+        {
+        }
+    }
+}
@@ -0,0 +1,186 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.api;
+
+import java.nio.ByteBuffer;
+
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+
+/**
+ * <p>A {@link WebTransportStream} is a reliable, ordered, stream of bytes
+ * within a {@link WebTransportSession}.</p>
+ * <p>Content is read with the {@link #read()}/{@link #demand(Runnable)} pair,
+ * in the same fashion of HTTP/3 streams:</p>
+ * <pre>{@code
+ * class Reader implements Runnable
+ * {
+ *     private final WebTransportStream stream;
+ *
+ *     public void run()
+ *     {
+ *         while (true)
+ *         {
+ *             WebTransportStream.Chunk chunk = stream.read();
+ *             if (chunk == null)
+ *             {
+ *                 stream.demand(this);
+ *                 return;
+ *             }
+ *             // Consume the chunk.
+ *             chunk.release();
+ *             if (chunk.isLast())
+ *                 return;
+ *         }
+ *     }
+ * }
+ * }</pre>
+ * <p>Content is written with {@link #write(boolean, ByteBuffer, Callback)};
+ * only one write at a time may be outstanding.</p>
+ * <p>Unidirectional streams are read-only for the receiving peer and
+ * write-only for the peer that opened them.</p>
+ */
+public interface WebTransportStream
+{
+    /**
+     * @return the QUIC id of this stream
+     */
+    public long getId();
+
+    /**
+     * @return the session this stream belongs to
+     */
+    public WebTransportSession getSession();
+
+    /**
+     * @return whether this stream is bidirectional
+     */
+    public boolean isBidirectional();
+
+    /**
+     * @return whether this stream has been opened by the local peer
+     */
+    public boolean isLocal();
+
+    /**
+     * <p>Reads a chunk of content, if available.</p>
+     * <p>The returned chunk must be {@link Chunk#release() released}
+     * after its content has been consumed.</p>
+     *
+     * @return a chunk of content, or {@code null} if no content is available
+     * @see #demand(Runnable)
+     */
+    public Chunk read();
+
+    /**
+     * <p>Demands that the given {@code demandCallback} is invoked
+     * when content is available to be {@link #read() read}.</p>
+     *
+     * @param demandCallback the callback to invoke when content is available
+     */
+    public void demand(Runnable demandCallback);
+
+    /**
+     * <p>Writes the given content.</p>
+     *
+     * @param last whether the content is the last of the stream
+     * @param byteBuffer the content to write
+     * @param callback the callback notified when the write completes
+     */
+    public void write(boolean last, ByteBuffer byteBuffer, Callback callback);
+
+    /**
+     * <p>Fails this stream, resetting it in both directions.</p>
+     *
+     * @param failure the failure
+     */
+    public void fail(Throwable failure);
+
+    /**
+     * <p>A chunk of content read from a {@link WebTransportStream}.</p>
+     */
+    public static class Chunk
+    {
+        /**
+         * <p>The last, empty, chunk of content of a stream.</p>
+         */
+        public static final Chunk EOF = new Chunk(BufferUtil.EMPTY_BUFFER, true, null, null);
+
+        private final ByteBuffer byteBuffer;
+        private final boolean last;
+        private final Throwable failure;
+        private final Runnable release;
+
+        public Chunk(ByteBuffer byteBuffer, boolean last, Runnable release)
+        {
+            this(byteBuffer, last, null, release);
+        }
+
+        private Chunk(ByteBuffer byteBuffer, boolean last, Throwable failure, Runnable release)
+        {
+            this.byteBuffer = byteBuffer;
+            this.last = last;
+            this.failure = failure;
+            this.release = release;
+        }
+
+        /**
+         * @param failure the stream failure
+         * @return a last chunk that reports the given failure
+         */
+        public static Chunk from(Throwable failure)
+        {
+            return new Chunk(BufferUtil.EMPTY_BUFFER, true, failure, null);
+        }
+
+        /**
+         * @return the content of this chunk
+         */
+        public ByteBuffer getByteBuffer()
+        {
+            return byteBuffer;
+        }
+
+        /**
+         * @return whether this is the last chunk of the stream
+         */
+        public boolean isLast()
+        {
+            return last;
+        }
+
+        /**
+         * @return the stream failure, or {@code null} if the stream did not fail
+         */
+        public Throwable getFailure()
+        {
+            return failure;
+        }
+
+        /**
+         * <p>Releases this chunk, after its content has been consumed.</p>
+         */
+        public void release()
+        {
+            if (release != null)
+                release.run();
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s@%x[%s,last=%b,failure=%s]", getClass().getSimpleName(), hashCode(), BufferUtil.toDetailString(byteBuffer), last, failure);
+        }
+    }
+}
@@ -0,0 +1,340 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.internal;
+
+import java.net.SocketAddress;
+import java.nio.ByteBuffer;
+import java.nio.channels.ClosedChannelException;
+import java.util.ArrayList;
+import java.util.Collection;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ConcurrentHashMap;
+
+import org.eclipse.jetty.http3.internal.HTTP3ErrorCode;
+import org.eclipse.jetty.http3.internal.HTTP3Session;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
+import org.eclipse.jetty.quic.common.StreamType;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.webtransport.api.WebTransportSession;
+import org.eclipse.jetty.webtransport.api.WebTransportStream;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link WebTransportSession} established over an HTTP/3 CONNECT stream.</p>
+ * <p>The CONNECT stream is tunnelled via a {@link HTTP3StreamEndPoint}, whose
+ * {@link Connection} is a {@link WebTransportSessionConnection}; the session
+ * streams are QUIC streams linked to the session by their preface.</p>
+ */
+public class HTTP3WebTransportSession implements WebTransportSession
+{
+    private static final Logger LOG = LoggerFactory.getLogger(HTTP3WebTransportSession.class);
+
+    private final AutoLock lock = new AutoLock();
+    private final Map<Long, HTTP3WebTransportStream> streams = new ConcurrentHashMap<>();
+    private final List<HTTP3WebTransportStream> queued = new ArrayList<>();
+    private final HTTP3StreamEndPoint tunnel;
+    private final HTTP3Session session;
+    private final long sessionId;
+    private final Listener listener;
+    private boolean opened;
+    private boolean closed;
+
+    public HTTP3WebTransportSession(HTTP3StreamEndPoint tunnel, Listener listener)
+    {
+        this.tunnel = tunnel;
+        this.session = tunnel.getStream().getSession();
+        this.sessionId = tunnel.getStream().getId();
+        this.listener = listener;
+        session.addWebTransportSession(sessionId, this::newRemoteStream);
+    }
+
+    @Override
+    public long getId()
+    {
+        return sessionId;
+    }
+
+    public HTTP3StreamEndPoint getTunnel()
+    {
+        return tunnel;
+    }
+
+    @Override
+    public SocketAddress getLocalSocketAddress()
+    {
+        return tunnel.getLocalSocketAddress();
+    }
+
+    @Override
+    public SocketAddress getRemoteSocketAddress()
+    {
+        return tunnel.getRemoteSocketAddress();
+    }
+
+    @Override
+    public Collection<WebTransportStream> getStreams()
+    {
+        return new ArrayList<>(streams.values());
+    }
+
+    @Override
+    public CompletableFuture<WebTransportStream> newBidirectionalStream()
+    {
+        return newStream(true);
+    }
+
+    @Override
+    public CompletableFuture<WebTransportStream> newUnidirectionalStream()
+    {
+        return newStream(false);
+    }
+
+    private CompletableFuture<WebTransportStream> newStream(boolean bidirectional)
+    {
+        Promise.Completable<WebTransportStream> promise = new Promise.Completable<>();
+        if (!isOpen())
+        {
+            promise.failed(new ClosedChannelException());
+            return promise;
+        }
+        session.newWebTransportStream(bidirectional, endPoint -> new HTTP3WebTransportStream(this, endPoint, bidirectional, true, promise));
+        return promise;
+    }
+
+    private Connection newRemoteStream(QuicStreamEndPoint endPoint)
+    {
+        StreamType streamType = StreamType.from(endPoint.getStreamId());
+        boolean bidirectional = streamType == StreamType.CLIENT_BIDIRECTIONAL || streamType == StreamType.SERVER_BIDIRECTIONAL;
+        return new HTTP3WebTransportStream(this, endPoint, bidirectional, false, null);
+    }
+
+    @Override
+    public CompletableFuture<Void> sendDatagram(ByteBuffer payload)
+    {
+        return tunnel.getStream().datagram(payload).thenApply(stream -> null);
+    }
+
+    @Override
+    public boolean isOpen()
+    {
+        try (AutoLock l = lock.lock())
+        {
+            return !closed;
+        }
+    }
+
+    @Override
+    public void close()
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("closing {}", this);
+        // Closing the CONNECT stream closes the session.
+        tunnel.shutdownOutput();
+        terminate(null);
+    }
+
+    /**
+     * <p>Called when the CONNECT stream has been upgraded to the session tunnel.</p>
+     */
+    void onOpen()
+    {
+        List<HTTP3WebTransportStream> streams;
+        try (AutoLock l = lock.lock())
+        {
+            opened = true;
+            streams = new ArrayList<>(queued);
+            queued.clear();
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("opened {}", this);
+        notifyOpen();
+        // Notify the streams that arrived before the session was established.
+        streams.forEach(this::notifyStream);
+    }
+
+    /**
+     * <p>Called when a datagram associated with the CONNECT stream is received.</p>
+     *
+     * @param payload the datagram payload
+     */
+    public void onDatagram(ByteBuffer payload)
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("received datagram {} bytes on {}", payload.remaining(), this);
+        if (isOpen())
+            notifyDatagram(payload);
+    }
+
+    void onStreamOpened(HTTP3WebTransportStream stream)
+    {
+        boolean rejected = false;
+        boolean notify = false;
+        try (AutoLock l = lock.lock())
+        {
+            if (closed)
+            {
+                rejected = true;
+            }
+            else
+            {
+                streams.put(stream.getId(), stream);
+                if (!stream.isLocal())
+                {
+                    if (opened)
+                        notify = true;
+                    else
+                        queued.add(stream);
+                }
+            }
+        }
+
+        if (rejected)
+        {
+            stream.reset(HTTP3ErrorCode.WEBTRANSPORT_SESSION_GONE.code(), new ClosedChannelException());
+            return;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("opened {} on {}", stream, this);
+        if (notify)
+            notifyStream(stream);
+    }
+
+    void onStreamClosed(HTTP3WebTransportStream stream)
+    {
+        boolean removed = streams.remove(stream.getId()) != null;
+        if (removed && LOG.isDebugEnabled())
+            LOG.debug("closed {} on {}", stream, this);
+    }
+
+    /**
+     * <p>Terminates this session, resetting all its streams.</p>
+     *
+     * @param failure the failure that caused the termination, or {@code null}
+     */
+    public void terminate(Throwable failure)
+    {
+        terminate(failure, true);
+    }
+
+    /**
+     * <p>Terminates this session before it is established, for example
+     * because it has been rejected, without notifying the listener.</p>
+     *
+     * @param failure the failure that caused the termination
+     */
+    public void abort(Throwable failure)
+    {
+        terminate(failure, false);
+    }
+
+    private void terminate(Throwable failure, boolean notify)
+    {
+        List<HTTP3WebTransportStream> streams;
+        try (AutoLock l = lock.lock())
+        {
+            if (closed)
+                return;
+            closed = true;
+            queued.clear();
+            streams = new ArrayList<>(this.streams.values());
+            this.streams.clear();
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("terminating {}", this, failure);
+
+        session.removeWebTransportSession(sessionId);
+        Throwable cause = failure == null ? new ClosedChannelException() : failure;
+        streams.forEach(stream -> stream.reset(HTTP3ErrorCode.WEBTRANSPORT_SESSION_GONE.code(), cause));
+
+        if (!notify)
+            return;
+        if (failure != null)
+            notifyFailure(failure);
+        notifyClose();
+    }
+
+    private void notifyOpen()
+    {
+        try
+        {
+            listener.onOpen(this);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyStream(WebTransportStream stream)
+    {
+        try
+        {
+            listener.onStream(stream);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyDatagram(ByteBuffer payload)
+    {
+        try
+        {
+            listener.onDatagram(this, payload);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyFailure(Throwable failure)
+    {
+        try
+        {
+            listener.onFailure(this, failure);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    private void notifyClose()
+    {
+        try
+        {
+            listener.onClose(this);
+        }
+        catch (Throwable x)
+        {
+            LOG.info("failure notifying listener {}", listener, x);
+        }
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x#%d[streams=%d,open=%b]", getClass().getSimpleName(), hashCode(), getId(), streams.size(), isOpen());
+    }
+}
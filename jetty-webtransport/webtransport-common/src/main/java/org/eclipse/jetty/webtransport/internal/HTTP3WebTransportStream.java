@@ -0,0 +1,346 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.internal;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.List;
+
+import org.eclipse.jetty.http3.frames.FrameType;
+import org.eclipse.jetty.http3.internal.UnidirectionalStreamConnection;
+import org.eclipse.jetty.http3.internal.VarLenInt;
+import org.eclipse.jetty.io.AbstractConnection;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.io.Connection;
+import org.eclipse.jetty.quic.common.QuicStreamEndPoint;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.Promise;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.eclipse.jetty.webtransport.api.WebTransportSession;
+import org.eclipse.jetty.webtransport.api.WebTransportStream;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link WebTransportStream} over a QUIC stream.</p>
+ * <p>Local streams write the stream preface, made of the stream type and
+ * the session id, before being made available to applications; remote
+ * streams have their preface already parsed by the HTTP/3 implementation,
+ * which upgrades the QUIC stream to this {@link Connection}.</p>
+ */
+public class HTTP3WebTransportStream extends AbstractConnection implements WebTransportStream, Connection.UpgradeTo
+{
+    // SPEC: WebTransport application error code 0x00, mapped to the HTTP/3 error space.
+    private static final long APPLICATION_ERROR_CODE = 0x52E4A40FA8DBL;
+    private static final Logger LOG = LoggerFactory.getLogger(HTTP3WebTransportStream.class);
+
+    private final AutoLock lock = new AutoLock();
+    private final HTTP3WebTransportSession session;
+    private final boolean bidirectional;
+    private final boolean local;
+    private final Promise<WebTransportStream> promise;
+    private final ByteBufferPool byteBufferPool;
+    private ByteBuffer upgradeBuffer;
+    private Runnable demandCallback;
+    private Throwable failure;
+    private boolean inputDone;
+    private boolean outputDone;
+
+    public HTTP3WebTransportStream(HTTP3WebTransportSession session, QuicStreamEndPoint endPoint, boolean bidirectional, boolean local, Promise<WebTransportStream> promise)
+    {
+        super(endPoint, endPoint.getQuicSession().getExecutor());
+        this.session = session;
+        this.bidirectional = bidirectional;
+        this.local = local;
+        this.promise = promise;
+        this.byteBufferPool = endPoint.getQuicSession().getByteBufferPool();
+        // Unidirectional streams are write-only for the local
+        // peer that opened them, and read-only for the remote peer.
+        if (!bidirectional)
+        {
+            this.inputDone = local;
+            this.outputDone = !local;
+        }
+    }
+
+    @Override
+    public QuicStreamEndPoint getEndPoint()
+    {
+        return (QuicStreamEndPoint)super.getEndPoint();
+    }
+
+    @Override
+    public long getId()
+    {
+        return getEndPoint().getStreamId();
+    }
+
+    @Override
+    public WebTransportSession getSession()
+    {
+        return session;
+    }
+
+    @Override
+    public boolean isBidirectional()
+    {
+        return bidirectional;
+    }
+
+    @Override
+    public boolean isLocal()
+    {
+        return local;
+    }
+
+    @Override
+    public void onUpgradeTo(ByteBuffer buffer)
+    {
+        if (BufferUtil.hasContent(buffer))
+            upgradeBuffer = buffer;
+    }
+
+    @Override
+    public void onOpen()
+    {
+        super.onOpen();
+        if (isLocal())
+        {
+            long streamType = isBidirectional() ? FrameType.WEBTRANSPORT_STREAM.type() : UnidirectionalStreamConnection.WEBTRANSPORT_STREAM_TYPE;
+            long sessionId = session.getId();
+            ByteBuffer preface = ByteBuffer.allocate(VarLenInt.length(streamType) + VarLenInt.length(sessionId));
+            VarLenInt.encode(preface, streamType);
+            VarLenInt.encode(preface, sessionId);
+            preface.flip();
+            if (LOG.isDebugEnabled())
+                LOG.debug("writing preface for session #{} on {}", sessionId, this);
+            getEndPoint().write(Callback.from(this::onPrefaceWritten, this::onPrefaceFailed), preface);
+        }
+        else
+        {
+            session.onStreamOpened(this);
+        }
+    }
+
+    private void onPrefaceWritten()
+    {
+        session.onStreamOpened(this);
+        Throwable failure;
+        try (AutoLock l = lock.lock())
+        {
+            failure = this.failure;
+        }
+        if (failure == null)
+            promise.succeeded(this);
+        else
+            promise.failed(failure);
+    }
+
+    private void onPrefaceFailed(Throwable x)
+    {
+        reset(APPLICATION_ERROR_CODE, x);
+        promise.failed(x);
+    }
+
+    @Override
+    public Chunk read()
+    {
+        ByteBuffer buffer;
+        try (AutoLock l = lock.lock())
+        {
+            if (failure != null)
+                return Chunk.from(failure);
+            if (inputDone)
+                return Chunk.EOF;
+            buffer = upgradeBuffer;
+            upgradeBuffer = null;
+        }
+
+        if (buffer != null)
+            return new Chunk(buffer, false, null);
+
+        buffer = byteBufferPool.acquire(getInputBufferSize(), false);
+        try
+        {
+            int filled = getEndPoint().fill(buffer);
+            if (LOG.isDebugEnabled())
+                LOG.debug("filled {} on {}", filled, this);
+            if (filled > 0)
+            {
+                ByteBuffer byteBuffer = buffer;
+                return new Chunk(byteBuffer, false, () -> byteBufferPool.release(byteBuffer));
+            }
+            byteBufferPool.release(buffer);
+            if (filled == 0)
+                return null;
+            onInputDone();
+            return Chunk.EOF;
+        }
+        catch (IOException x)
+        {
+            byteBufferPool.release(buffer);
+            reset(APPLICATION_ERROR_CODE, x);
+            return Chunk.from(x);
+        }
+    }
+
+    @Override
+    public void demand(Runnable demandCallback)
+    {
+        boolean available;
+        try (AutoLock l = lock.lock())
+        {
+            if (this.demandCallback != null)
+                throw new IllegalStateException("demand pending");
+            this.demandCallback = demandCallback;
+            available = failure != null || inputDone || upgradeBuffer != null;
+        }
+        if (available)
+            getExecutor().execute(this::onFillable);
+        else
+            fillInterested();
+    }
+
+    @Override
+    public void onFillable()
+    {
+        Runnable demandCallback;
+        try (AutoLock l = lock.lock())
+        {
+            demandCallback = this.demandCallback;
+            this.demandCallback = null;
+        }
+        if (demandCallback != null)
+        {
+            try
+            {
+                demandCallback.run();
+            }
+            catch (Throwable x)
+            {
+                LOG.info("failure running demand callback {}", demandCallback, x);
+            }
+        }
+    }
+
+    @Override
+    protected void onFillInterestedFailed(Throwable cause)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (failure == null)
+                failure = cause;
+        }
+        onFillable();
+    }
+
+    @Override
+    protected boolean onReadTimeout(Throwable timeout)
+    {
+        // Idle timeouts are handled by the WebTransport session.
+        return false;
+    }
+
+    @Override
+    public void write(boolean last, ByteBuffer byteBuffer, Callback callback)
+    {
+        Throwable failure;
+        try (AutoLock l = lock.lock())
+        {
+            failure = this.failure;
+            if (failure == null && outputDone)
+                failure = new IllegalStateException(isBidirectional() ? "output closed" : "read-only stream");
+        }
+        if (failure != null)
+        {
+            callback.failed(failure);
+            return;
+        }
+
+        if (LOG.isDebugEnabled())
+            LOG.debug("writing last={} {} on {}", last, BufferUtil.toDetailString(byteBuffer), this);
+        getEndPoint().write(Callback.from(() ->
+        {
+            if (last)
+                onOutputDone();
+            callback.succeeded();
+        }, callback::failed), List.of(byteBuffer), last);
+    }
+
+    @Override
+    public void fail(Throwable failure)
+    {
+        reset(APPLICATION_ERROR_CODE, failure);
+    }
+
+    void reset(long error, Throwable failure)
+    {
+        try (AutoLock l = lock.lock())
+        {
+            if (this.failure != null)
+                return;
+            this.failure = failure;
+        }
+        if (LOG.isDebugEnabled())
+            LOG.debug("resetting with error 0x{} {}", Long.toHexString(error), this, failure);
+        // Closing the EndPoint fails the pending reads and writes.
+        getEndPoint().close(error, failure);
+        onFillable();
+    }
+
+    private void onInputDone()
+    {
+        boolean completed;
+        try (AutoLock l = lock.lock())
+        {
+            inputDone = true;
+            completed = outputDone;
+        }
+        if (completed)
+            complete();
+    }
+
+    private void onOutputDone()
+    {
+        boolean completed;
+        try (AutoLock l = lock.lock())
+        {
+            outputDone = true;
+            completed = inputDone;
+        }
+        if (completed)
+            complete();
+    }
+
+    private void complete()
+    {
+        if (LOG.isDebugEnabled())
+            LOG.debug("completed {}", this);
+        getEndPoint().getQuicSession().remove(getEndPoint(), null);
+    }
+
+    @Override
+    public void onClose(Throwable cause)
+    {
+        session.onStreamClosed(this);
+        super.onClose(cause);
+    }
+
+    @Override
+    public String toConnectionString()
+    {
+        return String.format("%s@%x#%d[%s,%s]", getClass().getSimpleName(), hashCode(), getId(), isBidirectional() ? "bidi" : "uni", isLocal() ? "local" : "remote");
+    }
+}
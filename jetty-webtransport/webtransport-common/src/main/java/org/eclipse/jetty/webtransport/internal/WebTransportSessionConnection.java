@@ -0,0 +1,118 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.internal;
+
+import java.io.IOException;
+import java.nio.ByteBuffer;
+import java.util.concurrent.Executor;
+
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
+import org.eclipse.jetty.io.AbstractConnection;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>The {@link org.eclipse.jetty.io.Connection} of the CONNECT stream
+ * that established a {@link HTTP3WebTransportSession}.</p>
+ * <p>The CONNECT stream content is made of capsules, that are currently
+ * discarded; the session is terminated when the CONNECT stream is closed.</p>
+ */
+public class WebTransportSessionConnection extends AbstractConnection
+{
+    private static final Logger LOG = LoggerFactory.getLogger(WebTransportSessionConnection.class);
+
+    private final ByteBufferPool byteBufferPool;
+    private final HTTP3WebTransportSession session;
+
+    public WebTransportSessionConnection(HTTP3StreamEndPoint endPoint, Executor executor, ByteBufferPool byteBufferPool, HTTP3WebTransportSession session)
+    {
+        super(endPoint, executor);
+        this.byteBufferPool = byteBufferPool;
+        this.session = session;
+    }
+
+    public HTTP3WebTransportSession getWebTransportSession()
+    {
+        return session;
+    }
+
+    @Override
+    public void onOpen()
+    {
+        super.onOpen();
+        session.onOpen();
+        fillInterested();
+    }
+
+    @Override
+    public void onFillable()
+    {
+        ByteBuffer buffer = byteBufferPool.acquire(getInputBufferSize(), false);
+        try
+        {
+            while (true)
+            {
+                int filled = getEndPoint().fill(buffer);
+                if (LOG.isDebugEnabled())
+                    LOG.debug("filled {} on {}", filled, this);
+                if (filled > 0)
+                {
+                    // Capsules are not supported yet, discard them.
+                    BufferUtil.clear(buffer);
+                }
+                else if (filled == 0)
+                {
+                    fillInterested();
+                    break;
+                }
+                else
+                {
+                    session.terminate(null);
+                    getEndPoint().close();
+                    break;
+                }
+            }
+        }
+        catch (IOException x)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("could not read from {}", this, x);
+            session.terminate(x);
+            getEndPoint().close(x);
+        }
+        finally
+        {
+            byteBufferPool.release(buffer);
+        }
+    }
+
+    @Override
+    public boolean onIdleExpired()
+    {
+        // The session expires only if it has no streams.
+        boolean expired = session.getStreams().isEmpty();
+        if (LOG.isDebugEnabled())
+            LOG.debug("idle timeout expired={} on {}", expired, this);
+        return expired;
+    }
+
+    @Override
+    public void onClose(Throwable cause)
+    {
+        session.terminate(cause);
+        super.onClose(cause);
+    }
+}
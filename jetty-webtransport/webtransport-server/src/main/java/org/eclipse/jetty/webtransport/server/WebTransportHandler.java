@@ -0,0 +1,151 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.server;
+
+import java.io.IOException;
+import java.util.Objects;
+import javax.servlet.ServletException;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http.MetaData;
+import org.eclipse.jetty.http3.internal.HTTP3StreamEndPoint;
+import org.eclipse.jetty.http3.server.HttpDatagrams;
+import org.eclipse.jetty.io.EndPoint;
+import org.eclipse.jetty.server.Connector;
+import org.eclipse.jetty.server.HttpChannel;
+import org.eclipse.jetty.server.HttpTransport;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.handler.HandlerWrapper;
+import org.eclipse.jetty.webtransport.api.WebTransportSession;
+import org.eclipse.jetty.webtransport.internal.HTTP3WebTransportSession;
+import org.eclipse.jetty.webtransport.internal.WebTransportSessionConnection;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>A {@link HandlerWrapper} that accepts WebTransport sessions over HTTP/3.</p>
+ * <p>WebTransport sessions are established via HTTP/3 extended CONNECT requests
+ * with the {@code webtransport} protocol; for each such request, the
+ * {@link Acceptor} decides whether to accept the session, returning the
+ * {@link WebTransportSession.Listener} that handles the session events,
+ * or rejects it by returning {@code null}.</p>
+ * <p>Other requests are forwarded to the wrapped handler.</p>
+ * <p>WebTransport requires the HTTP/3 connector to enable QUIC datagrams,
+ * and the HTTP/3 configuration to enable the CONNECT protocol, HTTP
+ * datagrams and WebTransport:</p>
+ * <pre>{@code
+ * HTTP3ServerConnector connector = ...;
+ * connector.getQuicConfiguration().setDatagramsEnabled(true);
+ * HTTP3Configuration http3Config = connector.getConnectionFactory(HTTP3ServerConnectionFactory.class).getHTTP3Configuration();
+ * http3Config.setConnectProtocolEnabled(true);
+ * http3Config.setDatagramsEnabled(true);
+ * http3Config.setWebTransportEnabled(true);
+ * }</pre>
+ */
+public class WebTransportHandler extends HandlerWrapper
+{
+    /**
+     * <p>The value of the {@code :protocol} pseudo-header of WebTransport CONNECT requests.</p>
+     */
+    public static final String PROTOCOL = "webtransport";
+    private static final String DRAFT_HEADER = "sec-webtransport-http3-draft";
+    private static final String DRAFT_VERSION = "draft02";
+    private static final Logger LOG = LoggerFactory.getLogger(WebTransportHandler.class);
+
+    private final Acceptor acceptor;
+
+    public WebTransportHandler(Acceptor acceptor)
+    {
+        this.acceptor = Objects.requireNonNull(acceptor);
+    }
+
+    public Acceptor getAcceptor()
+    {
+        return acceptor;
+    }
+
+    @Override
+    public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response) throws IOException, ServletException
+    {
+        HTTP3StreamEndPoint tunnel = getTunnel(baseRequest);
+        if (tunnel == null)
+        {
+            super.handle(target, baseRequest, request, response);
+            return;
+        }
+
+        baseRequest.setHandled(true);
+
+        WebTransportSession.Listener listener = acceptor.accept(request, response);
+        if (listener == null)
+        {
+            if (LOG.isDebugEnabled())
+                LOG.debug("rejected WebTransport session on {}", tunnel);
+            if (response.getStatus() == HttpStatus.OK_200)
+                response.setStatus(HttpStatus.FORBIDDEN_403);
+            return;
+        }
+
+        HTTP3WebTransportSession session = new HTTP3WebTransportSession(tunnel, listener);
+        HttpDatagrams.setListener(request, session::onDatagram);
+        Connector connector = baseRequest.getHttpChannel().getConnector();
+        WebTransportSessionConnection connection = new WebTransportSessionConnection(tunnel, connector.getExecutor(), connector.getByteBufferPool(), session);
+        request.setAttribute(HttpTransport.UPGRADE_CONNECTION_ATTRIBUTE, connection);
+        if (LOG.isDebugEnabled())
+            LOG.debug("accepted {}", session);
+
+        response.setStatus(HttpStatus.OK_200);
+        response.setHeader(DRAFT_HEADER, DRAFT_VERSION);
+    }
+
+    private HTTP3StreamEndPoint getTunnel(Request baseRequest)
+    {
+        if (!HttpMethod.CONNECT.is(baseRequest.getMethod()))
+            return null;
+        MetaData.Request metaData = baseRequest.getMetaData();
+        if (metaData == null || !PROTOCOL.equals(metaData.getProtocol()))
+            return null;
+        HttpChannel channel = baseRequest.getHttpChannel();
+        if (!channel.isTunnellingSupported())
+            return null;
+        EndPoint endPoint = channel.getTunnellingEndPoint();
+        if (!(endPoint instanceof HTTP3StreamEndPoint))
+            return null;
+        HTTP3StreamEndPoint tunnel = (HTTP3StreamEndPoint)endPoint;
+        if (!tunnel.getStream().getSession().isWebTransportEnabled())
+            return null;
+        return tunnel;
+    }
+
+    /**
+     * <p>Decides whether to accept WebTransport sessions.</p>
+     */
+    @FunctionalInterface
+    public interface Acceptor
+    {
+        /**
+         * <p>Accepts or rejects a WebTransport session.</p>
+         * <p>To reject the session, implementations return {@code null},
+         * and may set the response status, by default 403.</p>
+         *
+         * @param request the WebTransport CONNECT request
+         * @param response the response
+         * @return the listener of the WebTransport session events, or {@code null} to reject the session
+         */
+        public WebTransportSession.Listener accept(HttpServletRequest request, HttpServletResponse response);
+    }
+}
@@ -0,0 +1,294 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.webtransport.tests;
+
+import java.io.InputStream;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.security.KeyStore;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.http3.HTTP3Configuration;
+import org.eclipse.jetty.http3.server.HTTP3ServerConnectionFactory;
+import org.eclipse.jetty.http3.server.HTTP3ServerConnector;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.util.ssl.SslContextFactory;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.eclipse.jetty.webtransport.api.WebTransportSession;
+import org.eclipse.jetty.webtransport.api.WebTransportStream;
+import org.eclipse.jetty.webtransport.client.WebTransportClient;
+import org.eclipse.jetty.webtransport.server.WebTransportHandler;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.extension.ExtendWith;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+@ExtendWith(WorkDirExtension.class)
+public class WebTransportTest
+{
+    public WorkDir workDir;
+    private Server server;
+    private HTTP3ServerConnector connector;
+    private WebTransportClient client;
+
+    private void start(WebTransportHandler.Acceptor acceptor) throws Exception
+    {
+        QueuedThreadPool serverThreads = new QueuedThreadPool();
+        serverThreads.setName("server");
+        server = new Server(serverThreads);
+        SslContextFactory.Server sslContextFactory = new SslContextFactory.Server();
+        sslContextFactory.setKeyStorePath("src/test/resources/keystore.p12");
+        sslContextFactory.setKeyStorePassword("storepwd");
+        HTTP3ServerConnectionFactory connectionFactory = new HTTP3ServerConnectionFactory();
+        HTTP3Configuration http3Configuration = connectionFactory.getHTTP3Configuration();
+        http3Configuration.setConnectProtocolEnabled(true);
+        http3Configuration.setDatagramsEnabled(true);
+        http3Configuration.setWebTransportEnabled(true);
+        connector = new HTTP3ServerConnector(server, sslContextFactory, connectionFactory);
+        connector.getQuicConfiguration().setPemWorkDirectory(workDir.getEmptyPathDir());
+        connector.getQuicConfiguration().setDatagramsEnabled(true);
+        server.addConnector(connector);
+        server.setHandler(new WebTransportHandler(acceptor));
+        server.start();
+
+        KeyStore trustStore = KeyStore.getInstance("PKCS12");
+        try (InputStream is = getClass().getResourceAsStream("/keystore.p12"))
+        {
+            trustStore.load(is, "storepwd".toCharArray());
+        }
+        client = new WebTransportClient();
+        SslContextFactory.Client clientSslContextFactory = new SslContextFactory.Client();
+        clientSslContextFactory.setTrustStore(trustStore);
+        client.getClientConnector().setSslContextFactory(clientSslContextFactory);
+        QueuedThreadPool clientThreads = new QueuedThreadPool();
+        clientThreads.setName("client");
+        client.getClientConnector().setExecutor(clientThreads);
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose()
+    {
+        LifeCycle.stop(client);
+        LifeCycle.stop(server);
+    }
+
+    private URI newURI()
+    {
+        return URI.create("https://localhost:" + connector.getLocalPort() + "/wt");
+    }
+
+    private WebTransportSession connect(WebTransportSession.Listener listener) throws Exception
+    {
+        return client.connect(newURI(), listener).get(5, TimeUnit.SECONDS);
+    }
+
+    private static CompletableFuture<String> readAll(WebTransportStream stream)
+    {
+        CompletableFuture<String> result = new CompletableFuture<>();
+        StringBuilder builder = new StringBuilder();
+        new Runnable()
+        {
+            @Override
+            public void run()
+            {
+                while (true)
+                {
+                    WebTransportStream.Chunk chunk = stream.read();
+                    if (chunk == null)
+                    {
+                        stream.demand(this);
+                        return;
+                    }
+                    if (chunk.getFailure() != null)
+                    {
+                        result.completeExceptionally(chunk.getFailure());
+                        return;
+                    }
+                    builder.append(BufferUtil.toString(chunk.getByteBuffer(), StandardCharsets.UTF_8));
+                    chunk.release();
+                    if (chunk.isLast())
+                    {
+                        result.complete(builder.toString());
+                        return;
+                    }
+                }
+            }
+        }.run();
+        return result;
+    }
+
+    private static CompletableFuture<Void> write(WebTransportStream stream, String content)
+    {
+        Callback.Completable callback = new Callback.Completable();
+        stream.write(true, StandardCharsets.UTF_8.encode(content), callback);
+        return callback;
+    }
+
+    private WebTransportSession.Listener newEchoListener()
+    {
+        return new WebTransportSession.Listener()
+        {
+            @Override
+            public void onStream(WebTransportStream stream)
+            {
+                readAll(stream).thenAccept(content ->
+                {
+                    if (stream.isBidirectional())
+                        write(stream, content);
+                    else
+                        stream.getSession().newUnidirectionalStream().thenAccept(echo -> write(echo, content));
+                });
+            }
+
+            @Override
+            public void onDatagram(WebTransportSession session, ByteBuffer payload)
+            {
+                session.sendDatagram(BufferUtil.copy(payload));
+            }
+        };
+    }
+
+    @Test
+    public void testBidirectionalStreamEcho() throws Exception
+    {
+        start((request, response) -> newEchoListener());
+
+        WebTransportSession session = connect(new WebTransportSession.Listener() {});
+        WebTransportStream stream = session.newBidirectionalStream().get(5, TimeUnit.SECONDS);
+        assertTrue(stream.isBidirectional());
+        assertTrue(stream.isLocal());
+
+        CompletableFuture<String> echo = readAll(stream);
+        write(stream, "hello").get(5, TimeUnit.SECONDS);
+
+        assertEquals("hello", echo.get(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testUnidirectionalStreamEcho() throws Exception
+    {
+        start((request, response) -> newEchoListener());
+
+        CompletableFuture<String> echo = new CompletableFuture<>();
+        WebTransportSession session = connect(new WebTransportSession.Listener()
+        {
+            @Override
+            public void onStream(WebTransportStream stream)
+            {
+                assertFalse(stream.isBidirectional());
+                assertFalse(stream.isLocal());
+                readAll(stream).whenComplete((content, failure) ->
+                {
+                    if (failure == null)
+                        echo.complete(content);
+                    else
+                        echo.completeExceptionally(failure);
+                });
+            }
+        });
+        WebTransportStream stream = session.newUnidirectionalStream().get(5, TimeUnit.SECONDS);
+        write(stream, "hello").get(5, TimeUnit.SECONDS);
+
+        assertEquals("hello", echo.get(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testDatagramEcho() throws Exception
+    {
+        start((request, response) -> newEchoListener());
+
+        CompletableFuture<String> echo = new CompletableFuture<>();
+        WebTransportSession session = connect(new WebTransportSession.Listener()
+        {
+            @Override
+            public void onDatagram(WebTransportSession session, ByteBuffer payload)
+            {
+                echo.complete(BufferUtil.toString(payload, StandardCharsets.UTF_8));
+            }
+        });
+        session.sendDatagram(StandardCharsets.UTF_8.encode("hello")).get(5, TimeUnit.SECONDS);
+
+        assertEquals("hello", echo.get(5, TimeUnit.SECONDS));
+    }
+
+    @Test
+    public void testSessionRejected() throws Exception
+    {
+        start((request, response) -> null);
+
+        assertThrows(ExecutionException.class, () -> connect(new WebTransportSession.Listener() {}));
+    }
+
+    @Test
+    public void testClientCloseClosesServerSessionAndStreams() throws Exception
+    {
+        CountDownLatch serverStreamLatch = new CountDownLatch(1);
+        CountDownLatch serverCloseLatch = new CountDownLatch(1);
+        CompletableFuture<WebTransportStream> serverStream = new CompletableFuture<>();
+        start((request, response) -> new WebTransportSession.Listener()
+        {
+            @Override
+            public void onStream(WebTransportStream stream)
+            {
+                serverStream.complete(stream);
+                serverStreamLatch.countDown();
+            }
+
+            @Override
+            public void onClose(WebTransportSession session)
+            {
+                serverCloseLatch.countDown();
+            }
+        });
+
+        CountDownLatch clientCloseLatch = new CountDownLatch(1);
+        WebTransportSession session = connect(new WebTransportSession.Listener()
+        {
+            @Override
+            public void onClose(WebTransportSession session)
+            {
+                clientCloseLatch.countDown();
+            }
+        });
+        WebTransportStream stream = session.newBidirectionalStream().get(5, TimeUnit.SECONDS);
+        // Write some content without closing the stream, so that the server sees it.
+        Callback.Completable callback = new Callback.Completable();
+        stream.write(false, StandardCharsets.UTF_8.encode("hello"), callback);
+        callback.get(5, TimeUnit.SECONDS);
+        assertTrue(serverStreamLatch.await(5, TimeUnit.SECONDS));
+
+        session.close();
+
+        assertTrue(clientCloseLatch.await(5, TimeUnit.SECONDS));
+        assertTrue(serverCloseLatch.await(5, TimeUnit.SECONDS));
+        assertFalse(session.isOpen());
+        assertTrue(session.getStreams().isEmpty());
+        // The server stream has been reset when its session closed.
+        assertThrows(ExecutionException.class, () -> readAll(serverStream.get()).get(5, TimeUnit.SECONDS));
+    }
+}
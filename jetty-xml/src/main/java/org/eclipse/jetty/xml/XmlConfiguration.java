@@ -16,6 +16,7 @@ package org.eclipse.jetty.xml;
 import java.io.Closeable;
 import java.io.IOException;
 import java.io.InputStream;
+import java.io.StringReader;
 import java.lang.annotation.Annotation;
 import java.lang.reflect.Array;
 import java.lang.reflect.Constructor;
@@ -29,6 +30,8 @@ import java.net.InetAddress;
 import java.net.MalformedURLException;
 import java.net.URL;
 import java.net.UnknownHostException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
 import java.nio.file.Path;
 import java.nio.file.Paths;
 import java.util.ArrayList;
@@ -46,9 +49,11 @@ import java.util.Properties;
 import java.util.Queue;
 import java.util.ServiceLoader;
 import java.util.Set;
+import java.util.function.UnaryOperator;
 import java.util.stream.Collectors;
 import java.util.stream.Stream;
 
+import org.eclipse.jetty.util.IO;
 import org.eclipse.jetty.util.LazyList;
 import org.eclipse.jetty.util.Loader;
 import org.eclipse.jetty.util.MultiException;
@@ -56,10 +61,14 @@ import org.eclipse.jetty.util.Pool;
 import org.eclipse.jetty.util.StringUtil;
 import org.eclipse.jetty.util.TypeUtil;
 import org.eclipse.jetty.util.annotation.Name;
+import org.eclipse.jetty.util.component.Container;
 import org.eclipse.jetty.util.component.LifeCycle;
+import org.eclipse.jetty.util.component.Validatable;
+import org.eclipse.jetty.util.component.ValidationReport;
 import org.eclipse.jetty.util.resource.Resource;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
+import org.xml.sax.InputSource;
 import org.xml.sax.SAXException;
 
 /**
@@ -79,6 +88,15 @@ import org.xml.sax.SAXException;
  */
 public class XmlConfiguration
 {
+    /**
+     * The property that, when {@code true}, makes {@link #main(String...)} validate rather than start the configured objects.
+     */
+    public static final String VALIDATE_PROPERTY = "jetty.validate";
+    /**
+     * The property that, when {@code true}, makes {@link #main(String...)} add a {@link XmlConfigurationReloader}
+     * to the configured objects, so that the configuration can be reloaded while running.
+     */
+    public static final String RELOAD_PROPERTY = "jetty.reload";
     private static final Logger LOG = LoggerFactory.getLogger(XmlConfiguration.class);
     private static final Class<?>[] PRIMITIVES =
         {
@@ -232,6 +250,7 @@ public class XmlConfiguration
     private final Map<String, String> _propertyMap = new HashMap<>();
     private final Resource _location;
     private final String _dtd;
+    private XmlParser.Node _config;
     private ConfigurationProcessor _processor;
 
     public XmlParser getXmlParser()
@@ -250,12 +269,38 @@ public class XmlConfiguration
      * @throws SAXException if the configuration could not be parsed
      */
     public XmlConfiguration(Resource resource) throws SAXException, IOException
+    {
+        this(resource, null);
+    }
+
+    /**
+     * <p>Reads and parses the XML configuration file, after transforming its text
+     * with the given filter, for example to expand variables.</p>
+     * <p>When a filter is given, the XML configuration file is read as UTF-8 text.</p>
+     *
+     * @param resource the Resource to the XML configuration
+     * @param filter the function transforming the text of the XML configuration, or null
+     * @throws IOException if the configuration could not be read
+     * @throws SAXException if the configuration could not be parsed
+     */
+    public XmlConfiguration(Resource resource, UnaryOperator<String> filter) throws SAXException, IOException
     {
         XmlParser parser = getXmlParser();
         try (InputStream inputStream = resource.getInputStream())
         {
             _location = resource;
-            setConfig(parser.parse(inputStream));
+            if (filter == null)
+            {
+                setConfig(parser.parse(inputStream));
+            }
+            else
+            {
+                String xml = filter.apply(IO.toString(inputStream, StandardCharsets.UTF_8));
+                InputSource source = new InputSource(new StringReader(xml));
+                if (resource.getURI() != null)
+                    source.setSystemId(resource.getURI().toASCIIString());
+                setConfig(parser.parse(source));
+            }
             _dtd = parser.getDTD();
         }
         finally
@@ -277,6 +322,7 @@ public class XmlConfiguration
 
     private void setConfig(XmlParser.Node config)
     {
+        _config = config;
         if ("Configure".equals(config.getTag()))
         {
             _processor = new JettyXmlConfiguration();
@@ -359,6 +405,54 @@ public class XmlConfiguration
         return _processor.configure();
     }
 
+    /**
+     * @return the root node of the XML configuration
+     */
+    XmlParser.Node getConfigNode()
+    {
+        return _config;
+    }
+
+    /**
+     * @return the location of the XML configuration
+     */
+    Resource getLocation()
+    {
+        return _location;
+    }
+
+    /**
+     * <p>Evaluates the value of the given node, which must not create or invoke objects,
+     * for example a {@code <Property>} node or a {@code <Set>} node with a literal value.</p>
+     *
+     * @param node the node to evaluate
+     * @return the value of the node
+     * @throws Exception if the node cannot be evaluated
+     */
+    Object evaluate(XmlParser.Node node) throws Exception
+    {
+        return jettyProcessor().evaluate(node);
+    }
+
+    /**
+     * <p>Applies the given {@code <Set>} or {@code <Call>} node to the given object.</p>
+     *
+     * @param obj the object to apply the node to
+     * @param node the node to apply
+     * @throws Exception if the node cannot be applied
+     */
+    void apply(Object obj, XmlParser.Node node) throws Exception
+    {
+        jettyProcessor().apply(obj, node);
+    }
+
+    private JettyXmlConfiguration jettyProcessor()
+    {
+        if (_processor instanceof JettyXmlConfiguration)
+            return (JettyXmlConfiguration)_processor;
+        throw new IllegalStateException("Not a Jetty XML configuration: " + this);
+    }
+
     /**
      * Initialize a new Object defaults.
      * <p>This method must be called by any {@link ConfigurationProcessor} when it
@@ -539,6 +633,42 @@ public class XmlConfiguration
             }
         }
 
+        Object evaluate(XmlParser.Node node) throws Exception
+        {
+            switch (node.getTag())
+            {
+                case "Property":
+                    return propertyObj(node);
+                case "SystemProperty":
+                    return systemPropertyObj(node);
+                case "Env":
+                    return envObj(node);
+                case "Set":
+                    String property = node.getAttribute("property");
+                    Object value = value(null, node);
+                    if (value == null && property != null)
+                        value = _configuration.getProperties().get(property);
+                    return value;
+                default:
+                    return value(null, node);
+            }
+        }
+
+        void apply(Object obj, XmlParser.Node node) throws Exception
+        {
+            switch (node.getTag())
+            {
+                case "Set":
+                    set(obj, node);
+                    break;
+                case "Call":
+                    call(obj, node);
+                    break;
+                default:
+                    throw new IllegalArgumentException("Cannot apply " + node.getTag() + " in " + _configuration);
+            }
+        }
+
         /**
          * <p>Call a setter method.</p>
          * <p>This method makes a best effort to find a matching set method.
@@ -1849,6 +1979,14 @@ public class XmlConfiguration
      * <p>
      * Any IDs created in a configuration are passed to the next configuration file on the command line using {@link #getIdMap()}.
      * This allows objects with IDs created in one config file to be referenced in subsequent config files on the command line.
+     * <p>
+     * If the {@value #VALIDATE_PROPERTY} property is {@code true}, the configured objects are not started: all the configuration
+     * files are run even if some of them fail, the resulting objects that are {@link Validatable} are validated, and all the
+     * problems found are reported at once.
+     * <p>
+     * If the {@value #RELOAD_PROPERTY} property is {@code true}, a {@link XmlConfigurationReloader} for the same arguments
+     * is added as a bean to the first configured object that is a {@link Container}, so that the configuration can be
+     * reloaded while running, for example via JMX.
      *
      * @param args array of property and xml configuration filenames or {@link Resource}s.
      * @throws Exception if the XML configurations cannot be run
@@ -1879,6 +2017,9 @@ public class XmlConfiguration
                 }
             }
 
+            boolean validate = Boolean.parseBoolean(properties.getProperty(VALIDATE_PROPERTY));
+            ValidationReport report = new ValidationReport();
+
             // For all arguments, parse XMLs
             XmlConfiguration last = null;
             List<Object> objects = new ArrayList<>(args.length);
@@ -1886,27 +2027,58 @@ public class XmlConfiguration
             {
                 if (!arg.toLowerCase(Locale.ENGLISH).endsWith(".properties") && (arg.indexOf('=') < 0))
                 {
-                    XmlConfiguration configuration = new XmlConfiguration(Resource.newResource(arg));
-                    if (last != null)
-                        configuration.getIdMap().putAll(last.getIdMap());
-                    if (properties.size() > 0)
+                    try
                     {
-                        Map<String, String> props = new HashMap<>();
-                        properties.forEach((key, value) -> props.put(key.toString(),
-                            String.valueOf(value)));
-                        configuration.getProperties().putAll(props);
-                    }
+                        XmlConfiguration configuration = new XmlConfiguration(Resource.newResource(arg));
+                        if (last != null)
+                            configuration.getIdMap().putAll(last.getIdMap());
+                        if (properties.size() > 0)
+                        {
+                            Map<String, String> props = new HashMap<>();
+                            properties.forEach((key, value) -> props.put(key.toString(),
+                                String.valueOf(value)));
+                            configuration.getProperties().putAll(props);
+                        }
 
-                    Object obj = configuration.configure();
-                    if (obj != null && !objects.contains(obj))
-                        objects.add(obj);
-                    last = configuration;
+                        Object obj = configuration.configure();
+                        if (obj != null && !objects.contains(obj))
+                            objects.add(obj);
+                        last = configuration;
+                    }
+                    catch (Error | Exception e)
+                    {
+                        if (!validate)
+                            throw e;
+                        report.addProblem(arg, e);
+                    }
                 }
             }
 
             if (LOG.isDebugEnabled())
                 LOG.debug("objects={}", objects);
 
+            if (validate)
+            {
+                for (Object obj : objects)
+                {
+                    if (obj instanceof Validatable)
+                        report.validate((Validatable)obj);
+                }
+                System.out.println(report);
+                if (!report.isValid())
+                    throw new IllegalStateException("Validation failed with " + report.getProblems().size() + " problem(s)");
+                return;
+            }
+
+            if (last != null && Boolean.parseBoolean(properties.getProperty(RELOAD_PROPERTY)))
+            {
+                XmlConfigurationReloader reloader = new XmlConfigurationReloader(last.getIdMap(), withIniFiles(args, properties.getProperty("jetty.base")));
+                objects.stream()
+                    .filter(Container.class::isInstance)
+                    .findFirst()
+                    .ifPresent(container -> ((Container)container).addBean(reloader));
+            }
+
             // For all objects created by XmlConfigurations, start them if they are lifecycles.
             List<LifeCycle> started = new ArrayList<>(objects.size());
             for (Object obj : objects)
@@ -1940,6 +2112,33 @@ public class XmlConfiguration
         }
     }
 
+    /**
+     * <p>Appends to the given arguments the {@code start.ini} and {@code start.d/*.ini} files of the given
+     * Jetty base directory, so that their properties, which are otherwise passed by the start mechanism
+     * in a generated properties file, are reloaded from the files that are edited.</p>
+     */
+    private static String[] withIniFiles(String[] args, String jettyBase) throws IOException
+    {
+        if (jettyBase == null)
+            return args;
+        List<String> result = new ArrayList<>(Arrays.asList(args));
+        Path base = Paths.get(jettyBase);
+        Path startIni = base.resolve("start.ini");
+        if (Files.isRegularFile(startIni))
+            result.add(startIni.toString());
+        Path startDir = base.resolve("start.d");
+        if (Files.isDirectory(startDir))
+        {
+            try (Stream<Path> inis = Files.list(startDir))
+            {
+                inis.filter(path -> path.getFileName().toString().endsWith(".ini"))
+                    .sorted()
+                    .forEach(path -> result.add(path.toString()));
+            }
+        }
+        return result.toArray(new String[0]);
+    }
+
     private static class ConfigurationParser extends XmlParser implements Closeable
     {
         private final Pool<ConfigurationParser>.Entry _entry;
@@ -0,0 +1,701 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.xml;
+
+import java.io.BufferedReader;
+import java.io.InputStream;
+import java.io.InputStreamReader;
+import java.lang.reflect.InvocationTargetException;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.HashSet;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.Objects;
+import java.util.Properties;
+import java.util.Set;
+
+import org.eclipse.jetty.util.annotation.ManagedAttribute;
+import org.eclipse.jetty.util.annotation.ManagedObject;
+import org.eclipse.jetty.util.annotation.ManagedOperation;
+import org.eclipse.jetty.util.component.AbstractLifeCycle;
+import org.eclipse.jetty.util.resource.Resource;
+import org.eclipse.jetty.util.thread.AutoLock;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+/**
+ * <p>Reloads XML configurations, and the properties they depend on, while the configured objects are running.</p>
+ * <p>The reloader is created with the same arguments given to {@link XmlConfiguration#main(String...)}: XML
+ * files, {@code .properties} files and {@code name=value} properties; in addition, {@code .ini} files in the
+ * format of {@code start.ini} may be given, whose {@code name=value} lines are loaded as properties.
+ * When started, the reloader parses the configuration to record the baseline; when {@link #reload()} is
+ * called, the configuration is parsed again and compared with the baseline.</p>
+ * <p>A change is considered safe, and is applied to the running object, when:</p>
+ * <ul>
+ * <li>it is the value of a {@code <Set>} element, either literal or from {@code <Property>},
+ * {@code <SystemProperty>}, {@code <Env>} or {@code <Ref>} elements, for example a handler
+ * attribute, a limit, or the pattern of a rewrite rule;</li>
+ * <li>it is the value of an {@code <Arg>} of a {@code <Call>} element whose method name is one of the
+ * {@link #getReloadableCalls() reloadable calls}, for example {@code setLoggerLevel} to change log levels;</li>
+ * <li>the element is nested in an element that identifies a running object by id, such as
+ * {@code <Configure id="Server">}, {@code <New id="rule">} or {@code <Ref refid="Contexts">}.</li>
+ * </ul>
+ * <p>Any other change, such as elements added, removed or reordered, objects created with different
+ * arguments, or modules and options changed in {@code .ini} files, requires a restart and is rejected.
+ * The outcome is described by a {@link Report}: safe changes are applied even if other changes are rejected,
+ * but the baseline is only updated when no change is rejected, so that rejected changes are reported again
+ * until the objects are restarted.</p>
+ */
+@ManagedObject("Reloads XML configurations")
+public class XmlConfigurationReloader extends AbstractLifeCycle
+{
+    private static final Logger LOG = LoggerFactory.getLogger(XmlConfigurationReloader.class);
+
+    private final AutoLock _lock = new AutoLock();
+    private final Set<String> _reloadableCalls = new HashSet<>(Set.of("setLoggerLevel"));
+    private final Map<String, Object> _idMap;
+    private final List<String> _args;
+    private Snapshot _snapshot;
+
+    /**
+     * @param idMap the map of ids to the running objects, see {@link XmlConfiguration#getIdMap()}
+     * @param args the XML files, properties files, {@code .ini} files and properties to reload
+     */
+    public XmlConfigurationReloader(Map<String, Object> idMap, String... args)
+    {
+        _idMap = Objects.requireNonNull(idMap);
+        _args = List.of(args);
+    }
+
+    /**
+     * @return the configuration arguments that are reloaded
+     */
+    @ManagedAttribute("The configuration arguments that are reloaded")
+    public List<String> getArguments()
+    {
+        return _args;
+    }
+
+    /**
+     * @return the modifiable set of method names of the {@code <Call>} elements that are
+     * invoked again when their arguments change
+     */
+    public Set<String> getReloadableCalls()
+    {
+        return _reloadableCalls;
+    }
+
+    @Override
+    protected void doStart() throws Exception
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            _snapshot = load();
+        }
+        super.doStart();
+    }
+
+    @Override
+    protected void doStop() throws Exception
+    {
+        super.doStop();
+        try (AutoLock l = _lock.lock())
+        {
+            _snapshot = null;
+        }
+    }
+
+    /**
+     * <p>Reloads the configuration and applies the safe changes to the running objects.</p>
+     *
+     * @return the report of the changes applied and rejected
+     */
+    public Report reload()
+    {
+        try (AutoLock l = _lock.lock())
+        {
+            Report report = new Report();
+            if (_snapshot == null)
+            {
+                report.reject(toString(), "not started", null);
+                return report;
+            }
+
+            Snapshot snapshot;
+            try
+            {
+                snapshot = load();
+            }
+            catch (Throwable x)
+            {
+                LOG.warn("Unable to load configuration {}", _args, x);
+                report.reject(String.valueOf(_args), "cannot be loaded: " + x, x);
+                return report;
+            }
+
+            List<Change> changes = new ArrayList<>();
+            diff(_snapshot, snapshot, changes, report);
+            for (Change change : changes)
+            {
+                try
+                {
+                    change._configuration.apply(change._target, change._node);
+                    report.apply(change._location, change._message);
+                }
+                catch (Throwable x)
+                {
+                    LOG.warn("Unable to apply {}", change._location, x);
+                    report.reject(change._location, "cannot be applied: " + unwrap(x), x);
+                }
+            }
+
+            if (report.getRejected().isEmpty())
+                _snapshot = snapshot;
+            if (LOG.isDebugEnabled())
+                LOG.debug("{}", report);
+            return report;
+        }
+    }
+
+    /**
+     * <p>Reloads the configuration, see {@link #reload()}.</p>
+     *
+     * @return the description of the changes applied and rejected
+     */
+    @ManagedOperation(value = "Reloads the configuration and applies the safe changes", impact = "ACTION")
+    public String reloadConfiguration()
+    {
+        return reload().toString();
+    }
+
+    /**
+     * <p>Returns whether the given property of the given running object may be changed.</p>
+     * <p>This implementation returns {@code true}, relying on the setter to throw if the property
+     * cannot be changed while running; subclasses may restrict the properties that can be reloaded.</p>
+     *
+     * @param target the running object, or {@code null} for static methods
+     * @param name the name of the {@code <Set>} or {@code <Call>} element
+     * @return whether the property can be changed
+     */
+    protected boolean isReloadable(Object target, String name)
+    {
+        return true;
+    }
+
+    private Snapshot load() throws Exception
+    {
+        Properties properties = new Properties();
+        properties.putAll(System.getProperties());
+        List<String> options = new ArrayList<>();
+        List<String> xmls = new ArrayList<>();
+        for (String arg : _args)
+        {
+            String lower = arg.toLowerCase(Locale.ENGLISH);
+            if (arg.indexOf('=') >= 0)
+            {
+                int i = arg.indexOf('=');
+                properties.put(arg.substring(0, i), arg.substring(i + 1));
+            }
+            else if (lower.endsWith(".properties"))
+            {
+                try (InputStream inputStream = Resource.newResource(arg).getInputStream())
+                {
+                    properties.load(inputStream);
+                }
+            }
+            else if (lower.endsWith(".ini"))
+            {
+                loadIni(arg, properties, options);
+            }
+            else
+            {
+                xmls.add(arg);
+            }
+        }
+
+        Map<String, String> props = new HashMap<>();
+        properties.forEach((key, value) -> props.put(key.toString(), String.valueOf(value)));
+
+        List<XmlConfiguration> configurations = new ArrayList<>();
+        for (String xml : xmls)
+        {
+            XmlConfiguration configuration = new XmlConfiguration(Resource.newResource(xml));
+            configuration.getProperties().putAll(props);
+            configuration.getIdMap().putAll(_idMap);
+            configurations.add(configuration);
+        }
+        return new Snapshot(options, configurations);
+    }
+
+    private static void loadIni(String ini, Properties properties, List<String> options) throws Exception
+    {
+        try (BufferedReader reader = new BufferedReader(new InputStreamReader(Resource.newResource(ini).getInputStream(), StandardCharsets.UTF_8)))
+        {
+            String line;
+            while ((line = reader.readLine()) != null)
+            {
+                line = line.trim();
+                if (line.isEmpty() || line.startsWith("#"))
+                    continue;
+                if (line.startsWith("--"))
+                {
+                    options.add(line);
+                    continue;
+                }
+                int equals = line.indexOf('=');
+                if (equals > 0)
+                    properties.put(line.substring(0, equals).trim(), line.substring(equals + 1).trim());
+            }
+        }
+    }
+
+    private void diff(Snapshot oldSnapshot, Snapshot newSnapshot, List<Change> changes, Report report) throws Exception
+    {
+        if (!oldSnapshot._options.equals(newSnapshot._options))
+            report.reject("ini", "modules or options changed from " + oldSnapshot._options + " to " + newSnapshot._options, null);
+
+        if (oldSnapshot._configurations.size() != newSnapshot._configurations.size())
+        {
+            report.reject(String.valueOf(_args), "XML files added or removed", null);
+            return;
+        }
+
+        for (int i = 0; i < oldSnapshot._configurations.size(); ++i)
+        {
+            XmlConfiguration oldConfiguration = oldSnapshot._configurations.get(i);
+            XmlConfiguration newConfiguration = newSnapshot._configurations.get(i);
+            XmlParser.Node oldRoot = oldConfiguration.getConfigNode();
+            XmlParser.Node newRoot = newConfiguration.getConfigNode();
+            if (!"Configure".equals(newRoot.getTag()))
+            {
+                if (!signature(oldConfiguration, oldRoot).equals(signature(newConfiguration, newRoot)))
+                    report.reject(location(newConfiguration, newRoot), "not a Jetty XML configuration", null);
+                continue;
+            }
+            if (!ownSignature(oldConfiguration, oldRoot).equals(ownSignature(newConfiguration, newRoot)))
+            {
+                report.reject(location(newConfiguration, newRoot), "configured object changed", null);
+                continue;
+            }
+            String id = newRoot.getAttribute("id");
+            Object target = id == null ? null : _idMap.get(id);
+            diff(target, oldConfiguration, oldRoot, newConfiguration, newRoot, changes, report);
+        }
+    }
+
+    private void diff(Object target, XmlConfiguration oldConfiguration, XmlParser.Node oldNode, XmlConfiguration newConfiguration, XmlParser.Node newNode, List<Change> changes, Report report) throws Exception
+    {
+        List<XmlParser.Node> oldChildren = elements(oldNode);
+        List<XmlParser.Node> newChildren = elements(newNode);
+        if (oldChildren.size() != newChildren.size())
+        {
+            report.reject(location(newConfiguration, newNode), "elements added or removed", null);
+            return;
+        }
+
+        for (int i = 0; i < newChildren.size(); ++i)
+        {
+            XmlParser.Node oldChild = oldChildren.get(i);
+            XmlParser.Node newChild = newChildren.get(i);
+            String location = location(newConfiguration, newChild);
+            if (!key(oldChild).equals(key(newChild)))
+            {
+                report.reject(location, "elements replaced or reordered", null);
+                continue;
+            }
+
+            switch (newChild.getTag())
+            {
+                case "Set":
+                {
+                    String oldSignature = signature(oldConfiguration, oldChild);
+                    String newSignature = signature(newConfiguration, newChild);
+                    if (oldSignature.equals(newSignature))
+                        break;
+                    if (!isSimple(oldChild) || !isSimple(newChild))
+                    {
+                        // The value is an object, look for changes in the object.
+                        if (!ownSignature(oldConfiguration, oldChild).equals(ownSignature(newConfiguration, newChild)))
+                            report.reject(location, "value must be created, invoked or computed", null);
+                        else
+                            diff(target, oldConfiguration, oldChild, newConfiguration, newChild, changes, report);
+                        break;
+                    }
+                    String reason = checkReloadable(target, newChild.getAttribute("name"));
+                    if (reason != null)
+                    {
+                        report.reject(location, reason, null);
+                        break;
+                    }
+                    Object value = newConfiguration.evaluate(newChild);
+                    changes.add(new Change(location, "set to " + value, target, newConfiguration, newChild));
+                    break;
+                }
+                case "Call":
+                {
+                    if (!ownSignature(oldConfiguration, oldChild).equals(ownSignature(newConfiguration, newChild)))
+                    {
+                        String name = newChild.getAttribute("name");
+                        // Static methods are invoked without a target object.
+                        boolean isStatic = newChild.getAttribute("class") != null;
+                        Object callTarget = isStatic ? null : target;
+                        String reason;
+                        if (!_reloadableCalls.contains(name) || !isSimpleCall(oldChild) || !isSimpleCall(newChild))
+                            reason = "method must not be invoked again";
+                        else if (isStatic)
+                            reason = isReloadable(null, name) ? null : "not reloadable";
+                        else
+                            reason = checkReloadable(callTarget, name);
+                        if (reason != null)
+                        {
+                            report.reject(location, reason, null);
+                            break;
+                        }
+                        changes.add(new Change(location, "invoked with " + arguments(newConfiguration, newChild), callTarget, newConfiguration, newChild));
+                        break;
+                    }
+                    diff(lookup(newChild), oldConfiguration, oldChild, newConfiguration, newChild, changes, report);
+                    break;
+                }
+                case "New":
+                case "Get":
+                case "Ref":
+                {
+                    if (!ownSignature(oldConfiguration, oldChild).equals(ownSignature(newConfiguration, newChild)))
+                    {
+                        report.reject(location, "object must be created with different arguments", null);
+                        break;
+                    }
+                    diff(lookup(newChild), oldConfiguration, oldChild, newConfiguration, newChild, changes, report);
+                    break;
+                }
+                case "Arg":
+                    // The arguments have been compared by the enclosing element.
+                    break;
+                default:
+                {
+                    if (!signature(oldConfiguration, oldChild).equals(signature(newConfiguration, newChild)))
+                        report.reject(location, "element changed", null);
+                    break;
+                }
+            }
+        }
+    }
+
+    private String checkReloadable(Object target, String name)
+    {
+        if (target == null)
+            return "object not identified, add an id to the enclosing element";
+        if (!isReloadable(target, name))
+            return "not reloadable";
+        return null;
+    }
+
+    private Object lookup(XmlParser.Node node)
+    {
+        String id = "Ref".equals(node.getTag()) ? node.getAttribute("refid", node.getAttribute("id")) : node.getAttribute("id");
+        return id == null ? null : _idMap.get(id);
+    }
+
+    private static List<XmlParser.Node> elements(XmlParser.Node node)
+    {
+        List<XmlParser.Node> elements = new ArrayList<>();
+        for (Object child : node)
+        {
+            if (child instanceof XmlParser.Node)
+                elements.add((XmlParser.Node)child);
+        }
+        return elements;
+    }
+
+    private static String key(XmlParser.Node node)
+    {
+        return node.getTag() + "[" + node.getAttribute("name") + "," + node.getAttribute("id") + "," + node.getAttribute("class") + "]";
+    }
+
+    /**
+     * @return whether the value of the node only depends on literals, properties and references
+     */
+    private static boolean isSimple(XmlParser.Node node)
+    {
+        for (XmlParser.Node child : elements(node))
+        {
+            switch (child.getTag())
+            {
+                case "Property":
+                case "SystemProperty":
+                case "Env":
+                case "Ref":
+                    break;
+                default:
+                    return false;
+            }
+        }
+        return true;
+    }
+
+    private static boolean isSimpleCall(XmlParser.Node node)
+    {
+        for (XmlParser.Node child : elements(node))
+        {
+            if (!"Arg".equals(child.getTag()) || !isSimple(child))
+                return false;
+        }
+        return true;
+    }
+
+    private static String arguments(XmlConfiguration configuration, XmlParser.Node node) throws Exception
+    {
+        List<Object> arguments = new ArrayList<>();
+        for (XmlParser.Node child : elements(node))
+        {
+            arguments.add(configuration.evaluate(child));
+        }
+        return arguments.toString();
+    }
+
+    /**
+     * @return the signature of the node, with its attributes and arguments but without the other nested elements
+     */
+    private static String ownSignature(XmlConfiguration configuration, XmlParser.Node node) throws Exception
+    {
+        StringBuilder builder = new StringBuilder();
+        attributes(builder, node);
+        for (Object child : node)
+        {
+            if (child instanceof XmlParser.Node)
+            {
+                XmlParser.Node element = (XmlParser.Node)child;
+                if ("Arg".equals(element.getTag()))
+                    builder.append(signature(configuration, element));
+            }
+            else
+            {
+                builder.append(child.toString().trim());
+            }
+        }
+        return builder.toString();
+    }
+
+    /**
+     * @return the signature of the node and of all its nested elements, with the properties resolved
+     */
+    private static String signature(XmlConfiguration configuration, XmlParser.Node node) throws Exception
+    {
+        StringBuilder builder = new StringBuilder();
+        signature(builder, configuration, node);
+        return builder.toString();
+    }
+
+    private static void signature(StringBuilder builder, XmlConfiguration configuration, XmlParser.Node node) throws Exception
+    {
+        attributes(builder, node);
+        switch (node.getTag())
+        {
+            case "Property":
+            case "SystemProperty":
+            case "Env":
+                builder.append("=").append(configuration.evaluate(node));
+                return;
+            case "Set":
+                String property = node.getAttribute("property");
+                if (property != null)
+                    builder.append("=").append(configuration.getProperties().get(property));
+                break;
+            default:
+                break;
+        }
+        builder.append("{");
+        for (Object child : node)
+        {
+            if (child instanceof XmlParser.Node)
+                signature(builder, configuration, (XmlParser.Node)child);
+            else
+                builder.append(child.toString().trim());
+        }
+        builder.append("}");
+    }
+
+    private static void attributes(StringBuilder builder, XmlParser.Node node)
+    {
+        builder.append(node.getTag());
+        XmlParser.Attribute[] attributes = node.getAttributes();
+        if (attributes != null)
+        {
+            for (XmlParser.Attribute attribute : attributes)
+            {
+                builder.append(" ").append(attribute.getName()).append("=").append(attribute.getValue());
+            }
+        }
+    }
+
+    private static String location(XmlConfiguration configuration, XmlParser.Node node)
+    {
+        String name = node.getAttribute("name");
+        String id = node.getAttribute("id");
+        StringBuilder builder = new StringBuilder();
+        builder.append(configuration.getLocation()).append(" ").append(node.getPath());
+        if (name != null)
+            builder.append("[name=").append(name).append("]");
+        if (id != null)
+            builder.append("[id=").append(id).append("]");
+        return builder.toString();
+    }
+
+    private static Throwable unwrap(Throwable failure)
+    {
+        while (failure instanceof InvocationTargetException && failure.getCause() != null)
+        {
+            failure = failure.getCause();
+        }
+        return failure;
+    }
+
+    @Override
+    public String toString()
+    {
+        return String.format("%s@%x%s", getClass().getSimpleName(), hashCode(), _args);
+    }
+
+    private static class Snapshot
+    {
+        private final List<String> _options;
+        private final List<XmlConfiguration> _configurations;
+
+        private Snapshot(List<String> options, List<XmlConfiguration> configurations)
+        {
+            _options = options;
+            _configurations = configurations;
+        }
+    }
+
+    private static class Change
+    {
+        private final String _location;
+        private final String _message;
+        private final Object _target;
+        private final XmlConfiguration _configuration;
+        private final XmlParser.Node _node;
+
+        private Change(String location, String message, Object target, XmlConfiguration configuration, XmlParser.Node node)
+        {
+            _location = location;
+            _message = message;
+            _target = target;
+            _configuration = configuration;
+            _node = node;
+        }
+    }
+
+    /**
+     * <p>The changes applied and rejected by a {@link #reload()}.</p>
+     */
+    public static class Report
+    {
+        private final List<Entry> _applied = new ArrayList<>();
+        private final List<Entry> _rejected = new ArrayList<>();
+
+        private void apply(String location, String message)
+        {
+            _applied.add(new Entry(location, message, null));
+        }
+
+        private void reject(String location, String message, Throwable cause)
+        {
+            _rejected.add(new Entry(location, message, cause));
+        }
+
+        /**
+         * @return the changes applied to the running objects
+         */
+        public List<Entry> getApplied()
+        {
+            return List.copyOf(_applied);
+        }
+
+        /**
+         * @return the changes that require a restart or that failed to be applied
+         */
+        public List<Entry> getRejected()
+        {
+            return List.copyOf(_rejected);
+        }
+
+        @Override
+        public String toString()
+        {
+            StringBuilder builder = new StringBuilder();
+            builder.append("Reload applied ").append(_applied.size()).append(" change(s), rejected ").append(_rejected.size()).append(" change(s)");
+            for (Entry entry : _applied)
+            {
+                builder.append(System.lineSeparator()).append("  applied ").append(entry);
+            }
+            for (Entry entry : _rejected)
+            {
+                builder.append(System.lineSeparator()).append("  rejected ").append(entry);
+            }
+            return builder.toString();
+        }
+    }
+
+    /**
+     * <p>A change found by a {@link #reload()}.</p>
+     */
+    public static class Entry
+    {
+        private final String _location;
+        private final String _message;
+        private final Throwable _cause;
+
+        private Entry(String location, String message, Throwable cause)
+        {
+            _location = location;
+            _message = message;
+            _cause = cause;
+        }
+
+        /**
+         * @return the location of the changed element
+         */
+        public String getLocation()
+        {
+            return _location;
+        }
+
+        /**
+         * @return the description of the change
+         */
+        public String getMessage()
+        {
+            return _message;
+        }
+
+        /**
+         * @return the failure that caused the change to be rejected, or {@code null}
+         */
+        public Throwable getCause()
+        {
+            return _cause;
+        }
+
+        @Override
+        public String toString()
+        {
+            return String.format("%s: %s", _location, _message);
+        }
+    }
+}
@@ -0,0 +1,211 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.xml;
+
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.HashMap;
+import java.util.Map;
+
+import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
+import org.eclipse.jetty.util.resource.PathResource;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static java.nio.charset.StandardCharsets.UTF_8;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.containsString;
+import static org.hamcrest.Matchers.empty;
+import static org.hamcrest.Matchers.hasSize;
+import static org.hamcrest.Matchers.is;
+
+public class XmlConfigurationReloaderTest
+{
+    private static final String BEAN = XmlConfigurationReloaderTest.class.getName() + "$Bean";
+
+    public WorkDir workDir;
+    private XmlConfigurationReloader reloader;
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (reloader != null)
+            reloader.stop();
+    }
+
+    private Path write(String name, String content) throws Exception
+    {
+        Path path = workDir.getPath().resolve(name);
+        Files.writeString(path, content, UTF_8);
+        return path;
+    }
+
+    private static String xml(String body)
+    {
+        return "<?xml version=\"1.0\"?>\n" +
+            "<!DOCTYPE Configure PUBLIC \"-//Jetty//Configure//EN\" \"https://www.eclipse.org/jetty/configure_10_0.dtd\">\n" +
+            "<Configure id=\"bean\" class=\"" + BEAN + "\">\n" +
+            body +
+            "</Configure>\n";
+    }
+
+    private Bean configure(Path xml, Path properties, String... names) throws Exception
+    {
+        XmlConfiguration configuration = new XmlConfiguration(new PathResource(xml));
+        Map<String, String> props = new HashMap<>();
+        for (String line : Files.readAllLines(properties, UTF_8))
+        {
+            int equals = line.indexOf('=');
+            if (equals > 0)
+                props.put(line.substring(0, equals), line.substring(equals + 1));
+        }
+        configuration.getProperties().putAll(props);
+        Bean bean = (Bean)configuration.configure();
+        reloader = new XmlConfigurationReloader(configuration.getIdMap(), xml.toString(), properties.toString());
+        reloader.getReloadableCalls().add("setLevel");
+        reloader.start();
+        return bean;
+    }
+
+    @Test
+    public void testSafeChangesApplied() throws Exception
+    {
+        Path properties = write("reload.ini", "--module=server\nbean.limit=10\n");
+        Path xml = write("reload.xml", xml(
+            "  <Set name=\"limit\"><Property name=\"bean.limit\" default=\"1\"/></Set>\n" +
+                "  <Set name=\"name\">one</Set>\n" +
+                "  <Call name=\"setLevel\"><Arg>org.example</Arg><Arg><Property name=\"bean.level\" default=\"INFO\"/></Arg></Call>\n" +
+                "  <Set name=\"child\"><New id=\"child\" class=\"" + BEAN + "\"><Set name=\"name\">child</Set></New></Set>\n"));
+        Bean bean = configure(xml, properties);
+        assertThat(bean.limit, is(10));
+        assertThat(bean.name, is("one"));
+        assertThat(bean.level, is("org.example=INFO"));
+        assertThat(bean.child.name, is("child"));
+
+        // No changes.
+        XmlConfigurationReloader.Report report = reloader.reload();
+        assertThat(report.getApplied(), empty());
+        assertThat(report.getRejected(), empty());
+
+        write("reload.ini", "--module=server\nbean.limit=20\nbean.level=DEBUG\n");
+        write("reload.xml", xml(
+            "  <Set name=\"limit\"><Property name=\"bean.limit\" default=\"1\"/></Set>\n" +
+                "  <Set name=\"name\">two</Set>\n" +
+                "  <Call name=\"setLevel\"><Arg>org.example</Arg><Arg><Property name=\"bean.level\" default=\"INFO\"/></Arg></Call>\n" +
+                "  <Set name=\"child\"><New id=\"child\" class=\"" + BEAN + "\"><Set name=\"name\">renamed</Set></New></Set>\n"));
+        report = reloader.reload();
+
+        assertThat(report.toString(), report.getRejected(), empty());
+        assertThat(report.getApplied(), hasSize(4));
+        assertThat(bean.limit, is(20));
+        assertThat(bean.name, is("two"));
+        assertThat(bean.level, is("org.example=DEBUG"));
+        assertThat(bean.child.name, is("renamed"));
+
+        // The baseline has been updated.
+        report = reloader.reload();
+        assertThat(report.getApplied(), empty());
+    }
+
+    @Test
+    public void testUnsafeChangesRejected() throws Exception
+    {
+        Path properties = write("reload.ini", "--module=server\nbean.limit=10\n");
+        Path xml = write("reload.xml", xml(
+            "  <Set name=\"limit\"><Property name=\"bean.limit\" default=\"1\"/></Set>\n" +
+                "  <Set name=\"name\">one</Set>\n" +
+                "  <Set name=\"frozen\">one</Set>\n"));
+        Bean bean = configure(xml, properties);
+        bean.started = true;
+
+        write("reload.ini", "--module=server\n--module=http\nbean.limit=20\n");
+        write("reload.xml", xml(
+            "  <Set name=\"limit\"><Property name=\"bean.limit\" default=\"1\"/></Set>\n" +
+                "  <Set name=\"name\">one</Set>\n" +
+                "  <Set name=\"frozen\">two</Set>\n" +
+                "  <Set name=\"child\"><New class=\"" + BEAN + "\"/></Set>\n"));
+        XmlConfigurationReloader.Report report = reloader.reload();
+
+        // The module was added, the child element was added.
+        assertThat(report.toString(), report.getRejected(), hasSize(2));
+        assertThat(report.toString(), containsString("modules or options changed"));
+        assertThat(report.toString(), containsString("elements added or removed"));
+        assertThat(bean.limit, is(10));
+
+        // Restore the elements, only the frozen property and the module are changed.
+        write("reload.xml", xml(
+            "  <Set name=\"limit\"><Property name=\"bean.limit\" default=\"1\"/></Set>\n" +
+                "  <Set name=\"name\">one</Set>\n" +
+                "  <Set name=\"frozen\">two</Set>\n"));
+        report = reloader.reload();
+
+        assertThat(report.toString(), report.getRejected(), hasSize(2));
+        assertThat(report.toString(), containsString("cannot be applied"));
+        assertThat(report.getApplied(), hasSize(1));
+        assertThat(bean.limit, is(20));
+        assertThat(bean.frozen, is("one"));
+    }
+
+    @Test
+    public void testNestedObjectWithoutIdRejected() throws Exception
+    {
+        Path properties = write("reload.properties", "bean.name=one\n");
+        Path xml = write("reload.xml", xml(
+            "  <Set name=\"child\"><New class=\"" + BEAN + "\"><Set name=\"name\"><Property name=\"bean.name\"/></Set></New></Set>\n"));
+        configure(xml, properties);
+
+        write("reload.properties", "bean.name=two\n");
+        XmlConfigurationReloader.Report report = reloader.reload();
+
+        assertThat(report.toString(), report.getRejected(), hasSize(1));
+        assertThat(report.toString(), containsString("object not identified"));
+    }
+
+    public static class Bean
+    {
+        private int limit;
+        private String name;
+        private String level;
+        private String frozen;
+        private Bean child;
+        private boolean started;
+
+        public void setLimit(int limit)
+        {
+            this.limit = limit;
+        }
+
+        public void setName(String name)
+        {
+            this.name = name;
+        }
+
+        public void setLevel(String logger, String level)
+        {
+            this.level = logger + "=" + level;
+        }
+
+        public void setFrozen(String frozen)
+        {
+            if (started)
+                throw new IllegalStateException("started");
+            this.frozen = frozen;
+        }
+
+        public void setChild(Bean child)
+        {
+            this.child = child;
+        }
+    }
+}
@@ -0,0 +1,28 @@
+# DO NOT EDIT THIS FILE - See: https://eclipse.dev/jetty/documentation/
+
+[description]
+Adds the zstd content coding to the content codings available to the
+compression module and to HttpClient, using the pure Java Zstandard
+implementation of the aircompressor library.
+
+[tags]
+server
+handler
+
+[depend]
+server
+
+[files]
+maven://io.airlift/aircompressor/${aircompressor.version}|lib/zstd/aircompressor-${aircompressor.version}.jar
+
+[ini]
+aircompressor.version?=@aircompressor.version@
+
+[lib]
+lib/jetty-zstd-${jetty.version}.jar
+lib/zstd/*.jar
+
+[license]
+The aircompressor library is released under the Apache 2.0 license.
+https://github.com/airlift/aircompressor
+http://www.apache.org/licenses/LICENSE-2.0.html
@@ -0,0 +1,186 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.zstd;
+
+import java.io.ByteArrayInputStream;
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.io.UncheckedIOException;
+import java.nio.ByteBuffer;
+import java.util.zip.ZipException;
+
+import io.airlift.compress.zstd.ZstdInputStream;
+import io.airlift.compress.zstd.ZstdOutputStream;
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.io.ByteBufferPool;
+import org.eclipse.jetty.util.BufferUtil;
+
+/**
+ * <p>The {@code zstd} {@link ContentCodec}, as defined by
+ * <a href="https://datatracker.ietf.org/doc/html/rfc8878">RFC 8878</a>,
+ * based on the pure Java implementation of the aircompressor library.</p>
+ * <p>The aircompressor encoder does not support compression levels, so the
+ * compression level is ignored.</p>
+ * <p>The aircompressor decoder is stream based, so the decoder buffers the encoded
+ * bytes until a whole Zstandard frame is available, and then decodes the frame.
+ * Content that is encoded as a single frame is therefore only decoded when all the
+ * encoded bytes have been received.</p>
+ */
+public class ZstdContentCodec extends ContentCodec
+{
+    public ZstdContentCodec()
+    {
+        super("zstd");
+    }
+
+    @Override
+    public int getDefaultLevel()
+    {
+        return 3;
+    }
+
+    @Override
+    public OutputStream newEncoder(OutputStream output, int level) throws IOException
+    {
+        return new ZstdOutputStream(output);
+    }
+
+    @Override
+    public Decoder newDecoder(ByteBufferPool byteBufferPool)
+    {
+        return new ZstdDecoder();
+    }
+
+    /**
+     * <p>Accumulates the encoded bytes, decoding them a frame at a time.</p>
+     */
+    private static class ZstdDecoder implements Decoder
+    {
+        private static final int FRAME_MAGIC = 0xFD2FB528;
+        private static final int SKIPPABLE_FRAME_MAGIC = 0x184D2A50;
+        private static final int SKIPPABLE_FRAME_MAGIC_MASK = 0xFFFFFFF0;
+
+        private ByteBuffer encoded = BufferUtil.EMPTY_BUFFER;
+
+        @Override
+        public ByteBuffer decode(ByteBuffer buffer)
+        {
+            if (buffer.hasRemaining())
+            {
+                ByteBuffer accumulated = ByteBuffer.allocate(encoded.remaining() + buffer.remaining());
+                accumulated.put(encoded).put(buffer).flip();
+                encoded = accumulated;
+            }
+
+            try
+            {
+                while (true)
+                {
+                    int length = frameLength(encoded);
+                    if (length < 0)
+                        return BufferUtil.EMPTY_BUFFER;
+
+                    ByteBuffer frame = encoded.slice();
+                    frame.limit(length);
+                    encoded.position(encoded.position() + length);
+
+                    if (frame.getInt(0) == Integer.reverseBytes(FRAME_MAGIC))
+                    {
+                        byte[] bytes = new byte[length];
+                        frame.get(bytes);
+                        try (InputStream input = new ZstdInputStream(new ByteArrayInputStream(bytes)))
+                        {
+                            byte[] decoded = input.readAllBytes();
+                            if (decoded.length > 0)
+                                return ByteBuffer.wrap(decoded);
+                        }
+                    }
+                }
+            }
+            catch (IOException x)
+            {
+                throw new UncheckedIOException(x);
+            }
+        }
+
+        /**
+         * @param buffer the encoded bytes
+         * @return the length of the frame at the beginning of the given buffer,
+         * or -1 if more bytes are needed to have a whole frame
+         * @throws ZipException if the bytes are not a valid frame
+         */
+        private static int frameLength(ByteBuffer buffer) throws ZipException
+        {
+            int start = buffer.position();
+            int limit = buffer.limit();
+            if (limit - start < 4)
+                return -1;
+
+            int magic = Integer.reverseBytes(buffer.getInt(start));
+            if ((magic & SKIPPABLE_FRAME_MAGIC_MASK) == SKIPPABLE_FRAME_MAGIC)
+            {
+                if (limit - start < 8)
+                    return -1;
+                long length = 8L + (Integer.reverseBytes(buffer.getInt(start + 4)) & 0xFFFFFFFFL);
+                return length > limit - start ? -1 : (int)length;
+            }
+            if (magic != FRAME_MAGIC)
+                throw new ZipException("Invalid zstd frame magic number");
+
+            // Frame header.
+            int offset = start + 4;
+            if (limit - offset < 1)
+                return -1;
+            int descriptor = buffer.get(offset++) & 0xFF;
+            int contentSizeFlag = descriptor >>> 6;
+            boolean singleSegment = (descriptor & 0x20) != 0;
+            boolean checksum = (descriptor & 0x04) != 0;
+            int dictionaryIdFlag = descriptor & 0x03;
+            if (!singleSegment)
+                offset += 1;
+            offset += new int[]{0, 1, 2, 4}[dictionaryIdFlag];
+            offset += new int[]{singleSegment ? 1 : 0, 2, 4, 8}[contentSizeFlag];
+
+            // Blocks.
+            while (true)
+            {
+                if (limit - offset < 3)
+                    return -1;
+                int header = (buffer.get(offset) & 0xFF) | (buffer.get(offset + 1) & 0xFF) << 8 | (buffer.get(offset + 2) & 0xFF) << 16;
+                offset += 3;
+                boolean lastBlock = (header & 0x01) != 0;
+                int type = (header >>> 1) & 0x03;
+                int size = header >>> 3;
+                switch (type)
+                {
+                    case 0: // Raw.
+                    case 2: // Compressed.
+                        offset += size;
+                        break;
+                    case 1: // RLE.
+                        offset += 1;
+                        break;
+                    default:
+                        throw new ZipException("Invalid zstd block type");
+                }
+                if (lastBlock)
+                    break;
+            }
+            if (checksum)
+                offset += 4;
+            return offset > limit ? -1 : offset - start;
+        }
+    }
+}
@@ -0,0 +1,83 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.zstd;
+
+import java.io.ByteArrayOutputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+
+import org.eclipse.jetty.http.ContentCodec;
+import org.eclipse.jetty.util.BufferUtil;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.is;
+
+public class ZstdContentCodecTest
+{
+    @Test
+    public void testDiscovered()
+    {
+        assertThat(ContentCodec.getContentCodec("zstd"), instanceOf(ZstdContentCodec.class));
+    }
+
+    @Test
+    public void testEncodeDecodeFrames() throws Exception
+    {
+        ContentCodec codec = new ZstdContentCodec();
+        String first = "Hello, Hello, Hello, Hello, Hello, ".repeat(100);
+        String second = "World! World! World! World! World! ".repeat(100);
+
+        ByteArrayOutputStream encoded = new ByteArrayOutputStream();
+        encode(codec, first, encoded);
+        // A skippable frame between the two frames.
+        encoded.write(new byte[]{0x50, 0x2A, 0x4D, 0x18, 2, 0, 0, 0, 0, 0});
+        encode(codec, second, encoded);
+
+        // Push the encoded bytes in small chunks.
+        ContentCodec.Decoder decoder = codec.newDecoder(null);
+        ByteBuffer buffer = ByteBuffer.wrap(encoded.toByteArray());
+        StringBuilder decoded = new StringBuilder();
+        int frames = 0;
+        while (buffer.hasRemaining())
+        {
+            ByteBuffer slice = buffer.slice();
+            slice.limit(Math.min(7, slice.remaining()));
+            buffer.position(buffer.position() + slice.limit());
+            while (true)
+            {
+                ByteBuffer chunk = decoder.decode(slice);
+                if (!chunk.hasRemaining())
+                    break;
+                ++frames;
+                decoded.append(BufferUtil.toString(chunk, StandardCharsets.UTF_8));
+            }
+        }
+
+        assertThat(frames, is(2));
+        assertThat(decoded.toString(), is(first + second));
+    }
+
+    private static void encode(ContentCodec codec, String content, OutputStream output) throws Exception
+    {
+        ByteArrayOutputStream frame = new ByteArrayOutputStream();
+        try (OutputStream encoder = codec.newEncoder(frame, codec.getDefaultLevel()))
+        {
+            encoder.write(content.getBytes(StandardCharsets.UTF_8));
+        }
+        output.write(frame.toByteArray());
+    }
+}
@@ -0,0 +1,111 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.server.jmh;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ThreadLocalRandom;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.http.pathmap.MatchedResource;
+import org.eclipse.jetty.http.pathmap.PathMappings;
+import org.eclipse.jetty.http.pathmap.UriTemplatePathSpec;
+import org.eclipse.jetty.server.handler.DefaultHandler;
+import org.eclipse.jetty.server.handler.RouterHandler;
+import org.openjdk.jmh.annotations.Benchmark;
+import org.openjdk.jmh.annotations.Measurement;
+import org.openjdk.jmh.annotations.Param;
+import org.openjdk.jmh.annotations.Scope;
+import org.openjdk.jmh.annotations.Setup;
+import org.openjdk.jmh.annotations.State;
+import org.openjdk.jmh.annotations.Threads;
+import org.openjdk.jmh.annotations.Warmup;
+import org.openjdk.jmh.runner.Runner;
+import org.openjdk.jmh.runner.RunnerException;
+import org.openjdk.jmh.runner.options.Options;
+import org.openjdk.jmh.runner.options.OptionsBuilder;
+
+/**
+ * <p>Compares the {@link RouterHandler} trie matcher with {@link PathMappings} of {@link UriTemplatePathSpec}s.</p>
+ */
+@State(Scope.Benchmark)
+@Threads(1)
+@Warmup(iterations = 6, time = 2000, timeUnit = TimeUnit.MILLISECONDS)
+@Measurement(iterations = 3, time = 2000, timeUnit = TimeUnit.MILLISECONDS)
+public class RouterBenchmark
+{
+    @Param({"10", "100"})
+    public static int resources;
+
+    private RouterHandler router;
+    private PathMappings<String> mappings;
+    private String[] paths;
+
+    @Setup
+    public void setUp()
+    {
+        router = new RouterHandler();
+        mappings = new PathMappings<>();
+        DefaultHandler handler = new DefaultHandler();
+        List<String> paths = new ArrayList<>();
+        for (int i = 0; i < resources; ++i)
+        {
+            String resource = "/api/v1/resource" + i;
+            for (String template : List.of(resource, resource + "/{id}", resource + "/{id}/items/{item}"))
+            {
+                router.get(template, handler);
+                mappings.put(new UriTemplatePathSpec(template), template);
+            }
+            paths.add(resource);
+            paths.add(resource + "/42");
+            paths.add(resource + "/42/items/abc");
+            paths.add(resource + "/42/missing");
+        }
+        this.paths = paths.toArray(String[]::new);
+    }
+
+    private String nextPath()
+    {
+        return paths[ThreadLocalRandom.current().nextInt(paths.length)];
+    }
+
+    @Benchmark
+    public Object testRouterHandler()
+    {
+        RouterHandler.Match match = router.match("GET", nextPath());
+        return match == null ? null : match.getParameters();
+    }
+
+    @Benchmark
+    public Object testPathMappings()
+    {
+        String path = nextPath();
+        MatchedResource<String> matched = mappings.getMatched(path);
+        if (matched == null)
+            return null;
+        Map<String, String> parameters = ((UriTemplatePathSpec)matched.getPathSpec()).getPathParams(path);
+        return parameters;
+    }
+
+    public static void main(String[] args) throws RunnerException
+    {
+        Options opt = new OptionsBuilder()
+            .include(RouterBenchmark.class.getSimpleName())
+            .forks(1)
+            .build();
+
+        new Runner(opt).run();
+    }
+}
@@ -38,6 +38,7 @@ public class PoolStrategyBenchmark
         "Pool.Random",
         "Pool.RoundRobin",
         "Pool.ThreadId",
+        "Pool.Striped",
     })
     public static String POOL_TYPE;
 
@@ -76,6 +77,9 @@ public class PoolStrategyBenchmark
             case "Pool.RoundRobin" :
                 pool = new Pool<>(Pool.StrategyType.ROUND_ROBIN, SIZE, CACHE);
                 break;
+            case "Pool.Striped" :
+                pool = new Pool<>(Pool.StrategyType.STRIPED, SIZE, CACHE);
+                break;
 
             default:
                 throw new IllegalStateException();
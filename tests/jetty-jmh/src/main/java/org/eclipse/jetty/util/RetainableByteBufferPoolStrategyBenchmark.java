@@ -0,0 +1,93 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.util;
+
+import org.eclipse.jetty.io.ArrayRetainableByteBufferPool;
+import org.eclipse.jetty.io.RetainableByteBuffer;
+import org.openjdk.jmh.annotations.Benchmark;
+import org.openjdk.jmh.annotations.Param;
+import org.openjdk.jmh.annotations.Scope;
+import org.openjdk.jmh.annotations.Setup;
+import org.openjdk.jmh.annotations.State;
+import org.openjdk.jmh.annotations.TearDown;
+import org.openjdk.jmh.infra.Blackhole;
+import org.openjdk.jmh.runner.Runner;
+import org.openjdk.jmh.runner.RunnerException;
+import org.openjdk.jmh.runner.options.Options;
+import org.openjdk.jmh.runner.options.OptionsBuilder;
+
+/**
+ * Compares the lookup strategies of the retained buffer buckets when
+ * every available processor concurrently acquires and releases buffers.
+ */
+@State(Scope.Benchmark)
+public class RetainableByteBufferPoolStrategyBenchmark
+{
+    private ArrayRetainableByteBufferPool pool;
+
+    @Param({
+        "THREAD_ID",
+        "STRIPED",
+    })
+    public static String STRATEGY;
+
+    @Param({
+        "1",
+        "4",
+    })
+    public static int BUFFERS;
+
+    @Setup
+    public void setUp() throws Exception
+    {
+        pool = new ArrayRetainableByteBufferPool(0, -1, -1, Integer.MAX_VALUE, -1L, -1L, Pool.StrategyType.valueOf(STRATEGY));
+    }
+
+    @TearDown
+    public void tearDown()
+    {
+        pool.clear();
+        pool = null;
+    }
+
+    @Benchmark
+    public void testAcquireRelease()
+    {
+        RetainableByteBuffer[] buffers = new RetainableByteBuffer[BUFFERS];
+        for (int i = 0; i < BUFFERS; i++)
+        {
+            buffers[i] = pool.acquire(2048, true);
+        }
+        // Hold the buffers for a while, as a connection would.
+        Blackhole.consumeCPU(50);
+        for (RetainableByteBuffer buffer : buffers)
+        {
+            buffer.release();
+        }
+    }
+
+    public static void main(String[] args) throws RunnerException
+    {
+        Options opt = new OptionsBuilder()
+            .include(RetainableByteBufferPoolStrategyBenchmark.class.getSimpleName())
+            .warmupIterations(3)
+            .measurementIterations(3)
+            .forks(1)
+            .threads(Runtime.getRuntime().availableProcessors())
+            // .addProfiler(GCProfiler.class)
+            .build();
+
+        new Runner(opt).run();
+    }
+}
@@ -0,0 +1,177 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.client;
+
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.CompletableFuture;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicReference;
+import javax.servlet.http.HttpServletRequest;
+import javax.servlet.http.HttpServletResponse;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpRequest;
+import org.eclipse.jetty.client.HttpResponseException;
+import org.eclipse.jetty.client.util.CapsuleResponseListener;
+import org.eclipse.jetty.http.CapsuleConnection;
+import org.eclipse.jetty.http.HttpMethod;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http2.client.HTTP2Client;
+import org.eclipse.jetty.http2.client.http.HttpClientTransportOverHTTP2;
+import org.eclipse.jetty.http2.server.HTTP2CServerConnectionFactory;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.Request;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.server.handler.AbstractHandler;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.Callback;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+
+public class CapsuleTunnelTest
+{
+    private static final String PROTOCOL = "echo-capsules";
+    private static final long ECHO = 0x2A;
+
+    private Server server;
+    private ServerConnector connector;
+    private HttpClient client;
+
+    private void start() throws Exception
+    {
+        QueuedThreadPool serverThreads = new QueuedThreadPool();
+        serverThreads.setName("server");
+        server = new Server(serverThreads);
+        connector = new ServerConnector(server, 1, 1, new HTTP2CServerConnectionFactory(new HttpConfiguration()));
+        server.addConnector(connector);
+        server.setHandler(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                if (!HttpMethod.CONNECT.is(request.getMethod()) || !PROTOCOL.equals(baseRequest.getMetaData().getProtocol()))
+                {
+                    response.setStatus(HttpStatus.BAD_REQUEST_400);
+                    return;
+                }
+                // Echo the ECHO capsules back to the client.
+                AtomicReference<CapsuleConnection> connection = new AtomicReference<>();
+                connection.set(baseRequest.getHttpChannel().upgradeToCapsules((type, value) ->
+                {
+                    if (type == ECHO)
+                        connection.get().send(type, value, Callback.NOOP);
+                }, 1024));
+                response.setStatus(HttpStatus.OK_200);
+                response.setHeader("Capsule-Protocol", "?1");
+            }
+        });
+        server.start();
+
+        QueuedThreadPool clientThreads = new QueuedThreadPool();
+        clientThreads.setName("client");
+        ClientConnector clientConnector = new ClientConnector();
+        clientConnector.setSelectors(1);
+        clientConnector.setExecutor(clientThreads);
+        client = new HttpClient(new HttpClientTransportOverHTTP2(new HTTP2Client(clientConnector)));
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (server != null)
+            server.stop();
+    }
+
+    private CapsuleResponseListener send(String protocol, CapsuleConnection.Listener listener)
+    {
+        HttpRequest request = (HttpRequest)client.newRequest(URI.create("http://localhost:" + connector.getLocalPort()))
+            .method(HttpMethod.CONNECT)
+            .path("/capsules")
+            .headers(headers -> headers.put("Capsule-Protocol", "?1"));
+        request.upgradeProtocol(protocol);
+        CapsuleResponseListener responseListener = new CapsuleResponseListener(client, listener, 1024);
+        request.send(responseListener);
+        return responseListener;
+    }
+
+    @Test
+    public void testEchoCapsules() throws Exception
+    {
+        start();
+
+        BlockingQueue<String> capsules = new LinkedBlockingQueue<>();
+        CompletableFuture<Throwable> closed = new CompletableFuture<>();
+        CapsuleResponseListener responseListener = send(PROTOCOL, new CapsuleConnection.Listener()
+        {
+            @Override
+            public void onCapsule(long type, ByteBuffer value)
+            {
+                capsules.offer(type + ":" + BufferUtil.toString(value, StandardCharsets.UTF_8));
+            }
+
+            @Override
+            public void onClose(Throwable failure)
+            {
+                closed.complete(failure);
+            }
+        });
+        CapsuleConnection connection = responseListener.getConnection().get(5, TimeUnit.SECONDS);
+
+        for (int i = 0; i < 3; ++i)
+        {
+            Callback.Completable callback = new Callback.Completable();
+            connection.send(ECHO, BufferUtil.toBuffer("capsule_" + i, StandardCharsets.UTF_8), callback);
+            callback.get(5, TimeUnit.SECONDS);
+            assertEquals(ECHO + ":capsule_" + i, capsules.poll(5, TimeUnit.SECONDS));
+        }
+
+        // Unknown capsule types are ignored by the server.
+        Callback.Completable callback = new Callback.Completable();
+        connection.send(0x2B, BufferUtil.toBuffer("ignored", StandardCharsets.UTF_8), callback);
+        callback.get(5, TimeUnit.SECONDS);
+        connection.send(ECHO, BufferUtil.toBuffer("last", StandardCharsets.UTF_8), Callback.NOOP);
+        assertEquals(ECHO + ":last", capsules.poll(5, TimeUnit.SECONDS));
+
+        connection.getEndPoint().close();
+        closed.get(5, TimeUnit.SECONDS);
+    }
+
+    @Test
+    public void testTunnelRejected() throws Exception
+    {
+        start();
+
+        CapsuleResponseListener responseListener = send("unknown", (type, value) -> {});
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> responseListener.getConnection().get(5, TimeUnit.SECONDS));
+        assertThat(failure.getCause(), instanceOf(HttpResponseException.class));
+        assertEquals(HttpStatus.BAD_REQUEST_400, ((HttpResponseException)failure.getCause()).getResponse().getStatus());
+    }
+}
@@ -0,0 +1,175 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.http.client;
+
+import java.net.InetSocketAddress;
+import java.net.SocketAddress;
+import java.net.URI;
+import java.nio.ByteBuffer;
+import java.nio.channels.DatagramChannel;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.BlockingQueue;
+import java.util.concurrent.ExecutionException;
+import java.util.concurrent.LinkedBlockingQueue;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.client.HttpClient;
+import org.eclipse.jetty.client.HttpResponseException;
+import org.eclipse.jetty.client.UDPTunnel;
+import org.eclipse.jetty.http.HttpStatus;
+import org.eclipse.jetty.http2.client.HTTP2Client;
+import org.eclipse.jetty.http2.client.http.HttpClientTransportOverHTTP2;
+import org.eclipse.jetty.http2.server.HTTP2CServerConnectionFactory;
+import org.eclipse.jetty.io.ClientConnector;
+import org.eclipse.jetty.proxy.ConnectHandler;
+import org.eclipse.jetty.server.HttpConfiguration;
+import org.eclipse.jetty.server.Server;
+import org.eclipse.jetty.server.ServerConnector;
+import org.eclipse.jetty.util.BufferUtil;
+import org.eclipse.jetty.util.IO;
+import org.eclipse.jetty.util.thread.QueuedThreadPool;
+import org.junit.jupiter.api.AfterEach;
+import org.junit.jupiter.api.Test;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+public class ConnectUDPTunnelTest
+{
+    private DatagramChannel udpServer;
+    private Thread udpEcho;
+    private Server proxy;
+    private ServerConnector proxyConnector;
+    private ConnectHandler connectHandler;
+    private HttpClient client;
+
+    private void start() throws Exception
+    {
+        udpServer = DatagramChannel.open();
+        udpServer.bind(new InetSocketAddress("localhost", 0));
+        udpEcho = new Thread(() ->
+        {
+            try
+            {
+                ByteBuffer buffer = ByteBuffer.allocate(2048);
+                while (true)
+                {
+                    buffer.clear();
+                    SocketAddress peer = udpServer.receive(buffer);
+                    buffer.flip();
+                    udpServer.send(buffer, peer);
+                }
+            }
+            catch (Throwable ignored)
+            {
+                // The UDP server has been closed.
+            }
+        });
+        udpEcho.start();
+
+        QueuedThreadPool proxyThreads = new QueuedThreadPool();
+        proxyThreads.setName("proxy");
+        proxy = new Server(proxyThreads);
+        proxyConnector = new ServerConnector(proxy, 1, 1, new HTTP2CServerConnectionFactory(new HttpConfiguration()));
+        proxy.addConnector(proxyConnector);
+        connectHandler = new ConnectHandler();
+        connectHandler.setConnectUDPEnabled(true);
+        proxy.setHandler(connectHandler);
+        proxy.start();
+
+        QueuedThreadPool clientThreads = new QueuedThreadPool();
+        clientThreads.setName("client");
+        ClientConnector clientConnector = new ClientConnector();
+        clientConnector.setSelectors(1);
+        clientConnector.setExecutor(clientThreads);
+        client = new HttpClient(new HttpClientTransportOverHTTP2(new HTTP2Client(clientConnector)));
+        client.start();
+    }
+
+    @AfterEach
+    public void dispose() throws Exception
+    {
+        if (client != null)
+            client.stop();
+        if (proxy != null)
+            proxy.stop();
+        IO.close(udpServer);
+        if (udpEcho != null)
+            udpEcho.join(5000);
+    }
+
+    private UDPTunnel newUDPTunnel()
+    {
+        URI proxyURI = URI.create("http://localhost:" + proxyConnector.getLocalPort());
+        return new UDPTunnel(client, proxyURI, "localhost", udpServer.socket().getLocalPort());
+    }
+
+    @Test
+    public void testUDPEcho() throws Exception
+    {
+        start();
+
+        BlockingQueue<ByteBuffer> payloads = new LinkedBlockingQueue<>();
+        UDPTunnel tunnel = newUDPTunnel();
+        tunnel.open(payloads::offer).get(5, TimeUnit.SECONDS);
+        assertTrue(tunnel.isOpen());
+
+        for (int i = 0; i < 3; ++i)
+        {
+            String content = "datagram_" + i;
+            tunnel.send(BufferUtil.toBuffer(content, StandardCharsets.UTF_8)).get(5, TimeUnit.SECONDS);
+            ByteBuffer payload = payloads.poll(5, TimeUnit.SECONDS);
+            assertEquals(content, BufferUtil.toString(payload, StandardCharsets.UTF_8));
+        }
+
+        // Empty UDP payloads are proxied as well.
+        tunnel.send(BufferUtil.EMPTY_BUFFER).get(5, TimeUnit.SECONDS);
+        ByteBuffer payload = payloads.poll(5, TimeUnit.SECONDS);
+        assertFalse(payload.hasRemaining());
+
+        tunnel.close();
+        tunnel.whenClosed().get(5, TimeUnit.SECONDS);
+        assertFalse(tunnel.isOpen());
+    }
+
+    @Test
+    public void testUDPTargetNotWhiteListed() throws Exception
+    {
+        start();
+        connectHandler.getUDPWhiteListHosts().add("localhost:1");
+
+        UDPTunnel tunnel = newUDPTunnel();
+        ExecutionException failure = assertThrows(ExecutionException.class, () -> tunnel.open(payload -> {}).get(5, TimeUnit.SECONDS));
+        assertThat(failure.getCause(), instanceOf(HttpResponseException.class));
+        assertEquals(HttpStatus.FORBIDDEN_403, ((HttpResponseException)failure.getCause()).getResponse().getStatus());
+    }
+
+    @Test
+    public void testUDPTunnelIdleTimeout() throws Exception
+    {
+        start();
+        connectHandler.setIdleTimeout(1000);
+
+        UDPTunnel tunnel = newUDPTunnel();
+        tunnel.open(payload -> {}).get(5, TimeUnit.SECONDS);
+
+        // The proxy closes the idle UDP socket and ends the tunnel.
+        tunnel.whenClosed().get(5, TimeUnit.SECONDS);
+        assertFalse(tunnel.isOpen());
+    }
+}
@@ -19,6 +19,7 @@ import java.io.InterruptedIOException;
 import java.nio.charset.StandardCharsets;
 import java.util.List;
 import java.util.Random;
+import java.util.concurrent.CopyOnWriteArrayList;
 import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.ExecutionException;
 import java.util.concurrent.TimeUnit;
@@ -835,6 +836,42 @@ public class  HttpClientTest extends AbstractTest<TransportScenario>
         assertEquals(200, response.getStatus());
     }
 
+    @ParameterizedTest
+    @ArgumentsSource(TransportProvider.class)
+    public void testStreamId(Transport transport) throws Exception
+    {
+        init(transport);
+        List<Long> streamIds = new CopyOnWriteArrayList<>();
+        scenario.start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request baseRequest, HttpServletRequest request, HttpServletResponse response)
+            {
+                baseRequest.setHandled(true);
+                streamIds.add(baseRequest.getHttpChannel().getStreamId());
+            }
+        });
+
+        for (int i = 0; i < 2; ++i)
+        {
+            ContentResponse response = scenario.client.newRequest(scenario.newURI())
+                .timeout(5, TimeUnit.SECONDS)
+                .send();
+            assertEquals(200, response.getStatus());
+        }
+
+        assertEquals(2, streamIds.size());
+        if (transport.isMultiplexed())
+        {
+            assertThat(streamIds.get(0), Matchers.greaterThanOrEqualTo(0L));
+            assertThat(streamIds.get(1), Matchers.greaterThan(streamIds.get(0)));
+        }
+        else
+        {
+            assertEquals(List.of(-1L, -1L), streamIds);
+        }
+    }
+
     private void sleep(long time) throws IOException
     {
         try
@@ -18,6 +18,7 @@ import java.io.IOException;
 import java.io.InputStream;
 import java.nio.charset.StandardCharsets;
 import java.util.Random;
+import java.util.concurrent.CompletableFuture;
 import java.util.concurrent.CountDownLatch;
 import java.util.concurrent.TimeUnit;
 import java.util.concurrent.atomic.AtomicBoolean;
@@ -109,6 +110,86 @@ public class HttpTrailersTest extends AbstractTest<TransportScenario>
         assertEquals(HttpStatus.OK_200, response.getStatus());
     }
 
+    @ParameterizedTest
+    @ArgumentsSource(TransportProvider.class)
+    public void testTrailersFutures(Transport transport) throws Exception
+    {
+        init(transport);
+        String trailerName = "Trailer";
+        String requestTrailerValue = "request";
+        String responseTrailerValue = "response";
+        scenario.start(new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                CompletableFuture<HttpFields> trailersFuture = jettyRequest.getTrailersFuture();
+                // Read the content first.
+                ServletInputStream input = jettyRequest.getInputStream();
+                while (true)
+                {
+                    int read = input.read();
+                    if (read < 0)
+                        break;
+                }
+
+                // The content has been fully read, so the future is completed.
+                assertTrue(trailersFuture.isDone());
+                HttpFields trailers = trailersFuture.join();
+                assertEquals(requestTrailerValue, trailers.get(trailerName));
+
+                HttpFields responseTrailers = HttpFields.build().put(trailerName, responseTrailerValue);
+                jettyRequest.getResponse().setTrailers(() -> responseTrailers);
+                response.getOutputStream().write("content".getBytes(StandardCharsets.UTF_8));
+            }
+        });
+
+        HttpFields trailers = HttpFields.build().put(trailerName, requestTrailerValue).asImmutable();
+        ContentResponse response = scenario.client.newRequest(scenario.newURI())
+            .method(HttpMethod.POST)
+            .body(new BytesRequestContent("abcdefghijklmnopqrstuvwxyz".getBytes(StandardCharsets.UTF_8)))
+            .trailers(() -> trailers)
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        HttpFields responseTrailers = response.getTrailersFuture().get(5, TimeUnit.SECONDS);
+        assertEquals(responseTrailerValue, responseTrailers.get(trailerName));
+        assertEquals(responseTrailerValue, response.getTrailers().get(trailerName));
+    }
+
+    @ParameterizedTest
+    @ArgumentsSource(TransportProvider.class)
+    public void testTrailersFuturesNoTrailers(Transport transport) throws Exception
+    {
+        init(transport);
+        scenario.start(new EmptyServerHandler()
+        {
+            @Override
+            protected void service(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                // Read the content first.
+                ServletInputStream input = jettyRequest.getInputStream();
+                while (true)
+                {
+                    int read = input.read();
+                    if (read < 0)
+                        break;
+                }
+
+                HttpFields trailers = jettyRequest.getTrailersFuture().join();
+                assertEquals(0, trailers.size());
+            }
+        });
+
+        ContentResponse response = scenario.client.newRequest(scenario.newURI())
+            .timeout(5, TimeUnit.SECONDS)
+            .send();
+
+        assertEquals(HttpStatus.OK_200, response.getStatus());
+        assertEquals(0, response.getTrailersFuture().get(5, TimeUnit.SECONDS).size());
+    }
+
     @ParameterizedTest
     @ArgumentsSource(TransportProvider.class)
     public void testEmptyRequestTrailers(Transport transport) throws Exception
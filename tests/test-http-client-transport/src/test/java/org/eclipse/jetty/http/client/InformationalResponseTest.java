@@ -30,6 +30,7 @@ import org.eclipse.jetty.client.ProtocolHandler;
 import org.eclipse.jetty.client.api.Response;
 import org.eclipse.jetty.client.api.Result;
 import org.eclipse.jetty.client.util.BufferingResponseListener;
+import org.eclipse.jetty.http.HttpFields;
 import org.eclipse.jetty.http.HttpHeader;
 import org.eclipse.jetty.http.HttpHeaderValue;
 import org.eclipse.jetty.http.HttpStatus;
@@ -43,6 +44,7 @@ import static org.eclipse.jetty.http.client.Transport.FCGI;
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.contains;
 import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.nullValue;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 
 public class InformationalResponseTest extends AbstractTest<TransportScenario>
@@ -228,4 +230,104 @@ public class InformationalResponseTest extends AbstractTest<TransportScenario>
         assertThat(listener.getContentAsString(), is("OK"));
         assertThat(hints, contains("one", "two", "three"));
     }
+
+    @ParameterizedTest
+    @ArgumentsSource(TransportProvider.class)
+    public void test103EarlyHintsInterleaved(Transport transport) throws Exception
+    {
+        init(transport);
+        scenario.start(new AbstractHandler()
+        {
+            @Override
+            public void handle(String target, Request jettyRequest, HttpServletRequest request, HttpServletResponse response) throws IOException
+            {
+                jettyRequest.setHandled(true);
+                org.eclipse.jetty.server.Response jettyResponse = jettyRequest.getResponse();
+                response.setHeader("Final", "yes");
+                jettyResponse.sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</one.css>; rel=preload"));
+                response.sendError(HttpStatus.PROCESSING_102);
+                jettyResponse.sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</two.js>; rel=preload"));
+                response.setStatus(200);
+                response.getOutputStream().print("OK");
+                // Hints cannot be sent after the response is committed.
+                response.flushBuffer();
+                if (jettyResponse.sendEarlyHints(HttpFields.build().add(HttpHeader.LINK, "</three.js>; rel=preload")))
+                    throw new IllegalStateException();
+            }
+        });
+        long idleTimeout = 10000;
+        scenario.setRequestIdleTimeout(idleTimeout);
+
+        List<String> interims = new CopyOnWriteArrayList<>();
+        scenario.client.getProtocolHandlers().put(new ProtocolHandler()
+        {
+            @Override
+            public String getName()
+            {
+                return "Interim";
+            }
+
+            @Override
+            public boolean accept(org.eclipse.jetty.client.api.Request request, Response response)
+            {
+                return HttpStatus.isInterim(response.getStatus());
+            }
+
+            @Override
+            public Response.Listener getResponseListener()
+            {
+                return new Response.Listener()
+                {
+                    @Override
+                    public void onSuccess(Response response)
+                    {
+                        var request = response.getRequest();
+                        HttpConversation conversation = ((HttpRequest)request).getConversation();
+                        // Reset the conversation listeners, since we are going to receive another response code
+                        conversation.updateResponseListeners(null);
+
+                        HttpExchange exchange = conversation.getExchanges().peekLast();
+                        if (exchange != null)
+                        {
+                            // The interim responses must not carry the final response headers.
+                            String link = response.getHeaders().get(HttpHeader.LINK);
+                            String fin = response.getHeaders().get("Final");
+                            // Not all transports send 102 responses.
+                            if (response.getStatus() == HttpStatus.EARLY_HINT_103)
+                                interims.add(link + (fin == null ? "" : " " + fin));
+                            exchange.resetResponse();
+                        }
+                        else
+                        {
+                            response.abort(new IllegalStateException("should not have accepted"));
+                        }
+                    }
+                };
+            }
+        });
+
+        CountDownLatch complete = new CountDownLatch(1);
+        AtomicReference<Response> response = new AtomicReference<>();
+        BufferingResponseListener listener = new BufferingResponseListener()
+        {
+            @Override
+            public void onComplete(Result result)
+            {
+                response.set(result.getResponse());
+                complete.countDown();
+            }
+        };
+        scenario.client.newRequest(scenario.newURI())
+            .method("GET")
+            .headers(headers -> headers.put(HttpHeader.EXPECT, HttpHeaderValue.PROCESSING))
+            .timeout(5, TimeUnit.SECONDS)
+            .send(listener);
+
+        assertTrue(complete.await(5, TimeUnit.SECONDS));
+        assertThat(response.get().getStatus(), is(200));
+        assertThat(response.get().getHeaders().get("Final"), is("yes"));
+        assertThat(response.get().getHeaders().get(HttpHeader.LINK), nullValue());
+        assertThat(listener.getContentAsString(), is("OK"));
+        assertThat(interims, contains("</one.css>; rel=preload", "</two.js>; rel=preload"));
+    }
 }
@@ -402,6 +402,16 @@ public class CustomRequestLogTest
         assertThat(log, is("ResponseHeader: value1, value2, -"));
     }
 
+    @Test
+    public void testLogRequestAttribute() throws Exception
+    {
+        testHandlerServerStart("RequestAttribute: %{Attribute1}n, %{Attribute2}n");
+
+        _connector.getResponse("GET /requestAttributes HTTP/1.0\n\n");
+        String log = _entries.poll(5, TimeUnit.SECONDS);
+        assertThat(log, is("RequestAttribute: value1, -"));
+    }
+
     @Test
     public void testLogQueryString() throws Exception
     {
@@ -654,6 +664,10 @@ public class CustomRequestLogTest
                 response.addHeader("Header1", "value1");
                 response.addHeader("Header2", "value2");
             }
+            else if (request.getRequestURI().contains("requestAttributes"))
+            {
+                request.setAttribute("Attribute1", "value1");
+            }
             else if (request.getRequestURI().contains("/abort"))
             {
                 response.getOutputStream().println("data");
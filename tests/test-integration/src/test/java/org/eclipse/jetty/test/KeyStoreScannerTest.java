@@ -14,16 +14,22 @@
 package org.eclipse.jetty.test;
 
 import java.io.IOException;
+import java.io.InputStream;
 import java.net.URL;
+import java.nio.charset.StandardCharsets;
 import java.nio.file.FileSystemException;
 import java.nio.file.Files;
 import java.nio.file.Path;
 import java.nio.file.Paths;
 import java.nio.file.StandardCopyOption;
+import java.security.KeyPairGenerator;
+import java.security.KeyStore;
 import java.security.SecureRandom;
 import java.security.cert.Certificate;
 import java.security.cert.X509Certificate;
+import java.util.Base64;
 import java.util.Calendar;
+import java.util.Collections;
 import javax.net.ssl.HttpsURLConnection;
 import javax.net.ssl.KeyManager;
 import javax.net.ssl.SSLContext;
@@ -44,6 +50,7 @@ import org.eclipse.jetty.toolchain.test.jupiter.WorkDir;
 import org.eclipse.jetty.toolchain.test.jupiter.WorkDirExtension;
 import org.eclipse.jetty.util.component.LifeCycle;
 import org.eclipse.jetty.util.ssl.KeyStoreScanner;
+import org.eclipse.jetty.util.ssl.PemKeyStoreProvider;
 import org.eclipse.jetty.util.ssl.SslContextFactory;
 import org.junit.jupiter.api.AfterEach;
 import org.junit.jupiter.api.BeforeEach;
@@ -52,7 +59,7 @@ import org.junit.jupiter.api.extension.ExtendWith;
 
 import static org.hamcrest.MatcherAssert.assertThat;
 import static org.hamcrest.Matchers.is;
-import static org.junit.jupiter.api.Assertions.assertThrows;
+import static org.junit.jupiter.api.Assertions.assertNotNull;
 import static org.junit.jupiter.api.Assertions.assertTrue;
 import static org.junit.jupiter.api.Assumptions.assumeFalse;
 
@@ -148,8 +155,74 @@ public class KeyStoreScannerTest
             keyStoreScanner.scan(5000);
         }
 
-        // The good keystore is removed, now the bad keystore now causes an exception.
-        assertThrows(Throwable.class, this::getCertificateFromServer);
+        // The bad keystore cannot be loaded, so the previous keystore is still used.
+        X509Certificate cert2 = getCertificateFromServer();
+        assertThat(getExpiryYear(cert2), is(2015));
+    }
+
+    @Test
+    public void testPemFilesHotReload() throws Exception
+    {
+        Path certificatePath = keystoreDir.resolve("certificate.pem");
+        Path keyPath = keystoreDir.resolve("key.pem");
+        writePem("oldKeyStore", certificatePath, keyPath);
+        start(sslContextFactory ->
+        {
+            sslContextFactory.setKeyStorePassword("storepwd");
+            sslContextFactory.setKeyStoreSource(new PemKeyStoreProvider(certificatePath, keyPath));
+        });
+
+        // Check the original certificate expiry.
+        X509Certificate cert1 = getCertificateFromServer();
+        assertThat(getExpiryYear(cert1), is(2015));
+
+        // Replace only the private key file, which does not match the certificate.
+        try (StacklessLogging ignored = new StacklessLogging(KeyStoreScanner.class))
+        {
+            KeyPairGenerator generator = KeyPairGenerator.getInstance("RSA");
+            generator.initialize(2048);
+            Files.writeString(keyPath, toPem("PRIVATE KEY", generator.generateKeyPair().getPrivate().getEncoded()));
+            assertTrue(keyStoreScanner.scan(5000));
+        }
+
+        // The reload failed, so the previous certificate is still used.
+        assertThat(getExpiryYear(getCertificateFromServer()), is(2015));
+
+        // Replace both files, the scanner monitors both.
+        writePem("newKeyStore", certificatePath, keyPath);
+        assertTrue(keyStoreScanner.scan(5000));
+
+        X509Certificate cert2 = getCertificateFromServer();
+        assertThat(getExpiryYear(cert2), is(2020));
+    }
+
+    private void writePem(String keystore, Path certificatePath, Path keyPath) throws Exception
+    {
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        try (InputStream inputStream = Files.newInputStream(MavenTestingUtils.getTestResourcePath(keystore)))
+        {
+            keyStore.load(inputStream, "storepwd".toCharArray());
+        }
+        String alias = null;
+        for (String a : Collections.list(keyStore.aliases()))
+        {
+            if (keyStore.isKeyEntry(a))
+                alias = a;
+        }
+        assertNotNull(alias);
+        StringBuilder chain = new StringBuilder();
+        for (Certificate certificate : keyStore.getCertificateChain(alias))
+        {
+            chain.append(toPem("CERTIFICATE", certificate.getEncoded()));
+        }
+        Files.writeString(certificatePath, chain.toString());
+        Files.writeString(keyPath, toPem("PRIVATE KEY", keyStore.getKey(alias, "keypwd".toCharArray()).getEncoded()));
+    }
+
+    private static String toPem(String type, byte[] bytes)
+    {
+        Base64.Encoder encoder = Base64.getMimeEncoder(64, "\n".getBytes(StandardCharsets.US_ASCII));
+        return "-----BEGIN " + type + "-----\n" + encoder.encodeToString(bytes) + "\n-----END " + type + "-----\n";
     }
 
     @Test
@@ -169,8 +242,8 @@ public class KeyStoreScannerTest
             keyStoreScanner.scan(5000);
         }
 
-        // The good keystore is removed, having no keystore causes an exception.
-        assertThrows(Throwable.class, this::getCertificateFromServer);
+        // The keystore cannot be reloaded, so the previous keystore is still used.
+        assertThat(getExpiryYear(getCertificateFromServer()), is(2015));
 
         // Switch to use keystore2 which has a later expiry date.
         useKeystore("newKeyStore");
@@ -13,11 +13,20 @@
 
 package org.eclipse.jetty.server.session;
 
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.servlet.ServletContextHandler;
 import org.junit.jupiter.api.AfterEach;
 import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
 import org.testcontainers.junit.jupiter.Testcontainers;
 
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.greaterThanOrEqualTo;
+import static org.hamcrest.Matchers.is;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
 /**
  * JDBCSessionDataStoreTest
  */
@@ -69,6 +78,107 @@ public class JDBCSessionDataStoreTest extends AbstractSessionDataStoreTest
         super.testCleanOrphans();
     }
 
+    @Test
+    public void testCleanOrphansInBatches() throws Exception
+    {
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        context.setContextPath("/test");
+        context.setClassLoader(_contextClassLoader);
+        JDBCSessionDataStoreFactory factory = (JDBCSessionDataStoreFactory)createSessionDataStoreFactory();
+        factory.setGracePeriodSec(GRACE_PERIOD_SEC);
+        factory.setScavengeBatchSize(2);
+        JDBCSessionDataStore store = (JDBCSessionDataStore)factory.getSessionDataStore(context.getSessionHandler());
+        SessionContext sessionContext = new SessionContext("foo", context.getServletContext());
+        store.initialize(sessionContext);
+
+        long now = System.currentTimeMillis();
+
+        //persist long ago expired sessions for our and other contexts
+        SessionData[] oldSessions = new SessionData[5];
+        for (int i = 0; i < oldSessions.length; ++i)
+        {
+            oldSessions[i] = store.newSessionData("00" + i, 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+            oldSessions[i].setExpiry(200);
+            oldSessions[i].setLastNode("me");
+            if (i % 2 == 0)
+                oldSessions[i].setContextPath("_other");
+            persistSession(oldSessions[i]);
+            assertTrue(checkSessionExists(oldSessions[i]));
+        }
+
+        //persist a non expired session for our context
+        SessionData unexpiredSession = store.newSessionData("010", 100, now + 10, now + 5, TimeUnit.MINUTES.toMillis(60));
+        unexpiredSession.setExpiry(now + TimeUnit.MINUTES.toMillis(10));
+        unexpiredSession.setLastNode("me");
+        persistSession(unexpiredSession);
+
+        store.start();
+
+        store.cleanOrphans(now - TimeUnit.SECONDS.toMillis(10 * GRACE_PERIOD_SEC));
+
+        for (SessionData oldSession : oldSessions)
+        {
+            assertFalse(checkSessionExists(oldSession));
+        }
+        assertTrue(checkSessionExists(unexpiredSession));
+        //5 orphans in batches of 2
+        assertThat(store.getStatementBatchCount(), is(3L));
+        assertThat(store.getStatementCount(), greaterThanOrEqualTo(6L));
+    }
+
+    @Test
+    public void testBatchedWrites() throws Exception
+    {
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        context.setContextPath("/test");
+        context.setClassLoader(_contextClassLoader);
+        JDBCSessionDataStoreFactory factory = (JDBCSessionDataStoreFactory)createSessionDataStoreFactory();
+        factory.setGracePeriodSec(GRACE_PERIOD_SEC);
+        factory.setWriteBatchSize(2);
+        JDBCSessionDataStore store = (JDBCSessionDataStore)factory.getSessionDataStore(context.getSessionHandler());
+        SessionContext sessionContext = new SessionContext("foo", context.getServletContext());
+        store.initialize(sessionContext);
+
+        store.start();
+
+        long now = System.currentTimeMillis();
+        SessionData data1 = store.newSessionData("bbb1", 100, 200, 199, -1);
+        data1.setLastNode(sessionContext.getWorkerName());
+        data1.setAttribute("a", "b");
+        SessionData data2 = store.newSessionData("bbb2", 100, 200, 199, -1);
+        data2.setLastNode(sessionContext.getWorkerName());
+        data2.setAttribute("a", "b");
+
+        //new sessions are written immediately
+        store.store("bbb1", data1);
+        store.store("bbb2", data2);
+        assertTrue(checkSessionPersisted(data1));
+        assertTrue(checkSessionPersisted(data2));
+        assertThat(store.getPendingUpdateCount(), is(0));
+
+        //updates are queued
+        data1.setAccessed(now);
+        data1.setAttribute("a", "c");
+        store.store("bbb1", data1);
+        assertThat(store.getPendingUpdateCount(), is(1));
+
+        //the batch is written when full
+        data2.setAccessed(now);
+        data2.setAttribute("a", "c");
+        store.store("bbb2", data2);
+        assertThat(store.getPendingUpdateCount(), is(0));
+        assertTrue(checkSessionPersisted(data1));
+        assertTrue(checkSessionPersisted(data2));
+
+        //pending updates are written before loading
+        data1.setAttribute("a", "d");
+        store.store("bbb1", data1);
+        assertThat(store.getPendingUpdateCount(), is(1));
+        SessionData loaded = store.load("bbb1");
+        assertThat((String)loaded.getAttribute("a"), is("d"));
+        assertThat(store.getPendingUpdateCount(), is(0));
+    }
+
     @Override
     public boolean checkSessionExists(SessionData data) throws Exception
     {
@@ -0,0 +1,259 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.util.Set;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.server.session.AbstractSessionDataStoreFactory;
+import org.eclipse.jetty.server.session.AbstractSessionDataStoreTest;
+import org.eclipse.jetty.server.session.SessionContext;
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataStore;
+import org.eclipse.jetty.server.session.SessionDataStoreFactory;
+import org.eclipse.jetty.servlet.ServletContextHandler;
+import org.junit.jupiter.api.AfterAll;
+import org.junit.jupiter.api.BeforeEach;
+import org.junit.jupiter.api.Test;
+import org.testcontainers.junit.jupiter.Testcontainers;
+
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.allOf;
+import static org.hamcrest.Matchers.containsInAnyOrder;
+import static org.hamcrest.Matchers.greaterThan;
+import static org.hamcrest.Matchers.is;
+import static org.hamcrest.Matchers.lessThanOrEqualTo;
+import static org.hamcrest.Matchers.nullValue;
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertFalse;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+/**
+ * RedisSessionDataStoreTest
+ */
+@Testcontainers(disabledWithoutDocker = true)
+public class RedisSessionDataStoreTest extends AbstractSessionDataStoreTest
+{
+    public RedisSessionDataStoreTest() throws Exception
+    {
+        super();
+    }
+
+    @BeforeEach
+    public void beforeEach()
+    {
+        RedisTestHelper.flushAll();
+    }
+
+    @AfterAll
+    public static void shutdown() throws Exception
+    {
+        RedisTestHelper.shutdown();
+    }
+
+    @Override
+    public SessionDataStoreFactory createSessionDataStoreFactory()
+    {
+        return RedisTestHelper.newSessionDataStoreFactory();
+    }
+
+    @Override
+    public void persistSession(SessionData data) throws Exception
+    {
+        RedisTestHelper.createSession(data, GRACE_PERIOD_SEC);
+    }
+
+    @Override
+    public void persistUnreadableSession(SessionData data) throws Exception
+    {
+        RedisTestHelper.createUnreadableSession(data, GRACE_PERIOD_SEC);
+    }
+
+    @Override
+    public boolean checkSessionExists(SessionData data) throws Exception
+    {
+        return RedisTestHelper.checkSessionExists(data);
+    }
+
+    @Override
+    public boolean checkSessionPersisted(SessionData data) throws Exception
+    {
+        ClassLoader old = Thread.currentThread().getContextClassLoader();
+        Thread.currentThread().setContextClassLoader(_contextClassLoader);
+        try
+        {
+            return RedisTestHelper.checkSessionPersisted(data);
+        }
+        finally
+        {
+            Thread.currentThread().setContextClassLoader(old);
+        }
+    }
+
+    private SessionDataStore newStartedStore(SessionContext[] sessionContext) throws Exception
+    {
+        ServletContextHandler context = new ServletContextHandler(ServletContextHandler.SESSIONS);
+        context.setContextPath("/redis");
+        context.setClassLoader(_contextClassLoader);
+        SessionDataStoreFactory factory = createSessionDataStoreFactory();
+        ((AbstractSessionDataStoreFactory)factory).setGracePeriodSec(GRACE_PERIOD_SEC);
+        SessionDataStore store = factory.getSessionDataStore(context.getSessionHandler());
+        sessionContext[0] = new SessionContext("foo", context.getServletContext());
+        store.initialize(sessionContext[0]);
+        store.start();
+        return store;
+    }
+
+    /**
+     * Test that stored sessions have a Redis TTL of their remaining
+     * lifetime plus the grace period, and that immortal sessions have none.
+     */
+    @Test
+    public void testTimeToLive() throws Exception
+    {
+        SessionContext[] sessionContext = new SessionContext[1];
+        SessionDataStore store = newStartedStore(sessionContext);
+
+        long now = System.currentTimeMillis();
+        long maxInactive = TimeUnit.MINUTES.toMillis(10);
+        SessionData mortal = store.newSessionData("ttl1", now, now, now, maxInactive);
+        mortal.setLastNode(sessionContext[0].getWorkerName());
+        mortal.calcAndSetExpiry(now);
+        store.store("ttl1", mortal);
+
+        long maxTtl = maxInactive + TimeUnit.SECONDS.toMillis(GRACE_PERIOD_SEC);
+        long ttl = RedisTestHelper.getJedis().pttl(RedisTestHelper.getKey(mortal));
+        assertThat(ttl, allOf(greaterThan(maxTtl - TimeUnit.MINUTES.toMillis(1)), lessThanOrEqualTo(maxTtl)));
+
+        SessionData immortal = store.newSessionData("ttl2", now, now, now, -1);
+        immortal.setLastNode(sessionContext[0].getWorkerName());
+        store.store("ttl2", immortal);
+        // A key without TTL has a pttl of -1.
+        assertEquals(-1, RedisTestHelper.getJedis().pttl(RedisTestHelper.getKey(immortal)));
+
+        // A session that becomes immortal loses its TTL.
+        mortal.setMaxInactiveMs(-1);
+        mortal.calcAndSetExpiry(now);
+        mortal.setDirty(true);
+        store.store("ttl1", mortal);
+        assertEquals(-1, RedisTestHelper.getJedis().pttl(RedisTestHelper.getKey(mortal)));
+    }
+
+    /**
+     * Test that the sorted set of expiry times follows the stores
+     * and the deletes of sessions.
+     */
+    @Test
+    public void testExpiryIndex() throws Exception
+    {
+        SessionContext[] sessionContext = new SessionContext[1];
+        SessionDataStore store = newStartedStore(sessionContext);
+
+        long now = System.currentTimeMillis();
+        SessionData data = store.newSessionData("idx1", now, now, now, TimeUnit.MINUTES.toMillis(10));
+        data.setLastNode(sessionContext[0].getWorkerName());
+        data.calcAndSetExpiry(now);
+        store.store("idx1", data);
+
+        String key = RedisTestHelper.getKey(data);
+        Double score = RedisTestHelper.getJedis().zscore(RedisTestHelper.getExpiryKey(), key);
+        assertEquals(data.getExpiry(), score.longValue());
+
+        // Immortal sessions are not in the expiry index.
+        data.setMaxInactiveMs(-1);
+        data.calcAndSetExpiry(now);
+        data.setDirty(true);
+        store.store("idx1", data);
+        assertThat(RedisTestHelper.getJedis().zscore(RedisTestHelper.getExpiryKey(), key), nullValue());
+
+        data.setMaxInactiveMs(TimeUnit.MINUTES.toMillis(10));
+        data.calcAndSetExpiry(now);
+        data.setDirty(true);
+        store.store("idx1", data);
+        assertTrue(store.delete("idx1"));
+        assertFalse(RedisTestHelper.checkSessionExists(data));
+        assertThat(RedisTestHelper.getJedis().zscore(RedisTestHelper.getExpiryKey(), key), nullValue());
+    }
+
+    /**
+     * Test that expired sessions are found from the expiry index only
+     * for the context of the store, and that sessions whose key
+     * has already been evicted by Redis are also reported.
+     */
+    @Test
+    public void testGetExpiredFromIndex() throws Exception
+    {
+        SessionContext[] sessionContext = new SessionContext[1];
+        SessionDataStore store = newStartedStore(sessionContext);
+
+        SessionData expired = store.newSessionData("exp1", 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+        expired.setLastNode("other");
+        expired.setExpiry(RECENT_TIMESTAMP);
+        persistSession(expired);
+
+        SessionData evicted = store.newSessionData("exp2", 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+        evicted.setLastNode("other");
+        evicted.setExpiry(RECENT_TIMESTAMP);
+        persistSession(evicted);
+        // Simulate the eviction of the key by Redis, leaving its index entry.
+        RedisTestHelper.getJedis().del(RedisTestHelper.getKey(evicted));
+
+        SessionData foreign = store.newSessionData("exp3", 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+        foreign.setContextPath("_other");
+        foreign.setLastNode("other");
+        foreign.setExpiry(RECENT_TIMESTAMP);
+        persistSession(foreign);
+
+        Set<String> expiredIds = ((RedisSessionDataStore)store).doGetExpired(System.currentTimeMillis());
+        assertThat(expiredIds, containsInAnyOrder("exp1", "exp2"));
+    }
+
+    /**
+     * Test that cleaning orphans removes both the keys and the index
+     * entries of sessions of all contexts that expired before the given time.
+     */
+    @Test
+    public void testCleanOrphansRemovesIndexEntries() throws Exception
+    {
+        SessionContext[] sessionContext = new SessionContext[1];
+        SessionDataStore store = newStartedStore(sessionContext);
+
+        long now = System.currentTimeMillis();
+        SessionData orphan = store.newSessionData("orph1", 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+        orphan.setExpiry(200);
+        orphan.setLastNode("other");
+        persistSession(orphan);
+
+        SessionData foreignOrphan = store.newSessionData("orph2", 100, 101, 100, TimeUnit.MINUTES.toMillis(60));
+        foreignOrphan.setContextPath("_other");
+        foreignOrphan.setExpiry(200);
+        foreignOrphan.setLastNode("other");
+        persistSession(foreignOrphan);
+
+        SessionData live = store.newSessionData("orph3", 100, now, now, TimeUnit.MINUTES.toMillis(60));
+        live.setExpiry(now + TimeUnit.MINUTES.toMillis(10));
+        live.setLastNode("other");
+        persistSession(live);
+
+        ((RedisSessionDataStore)store).doCleanOrphans(now - TimeUnit.SECONDS.toMillis(10 * GRACE_PERIOD_SEC));
+
+        assertFalse(checkSessionExists(orphan));
+        assertFalse(checkSessionExists(foreignOrphan));
+        assertTrue(checkSessionExists(live));
+        String expiryKey = RedisTestHelper.getExpiryKey();
+        assertThat(RedisTestHelper.getJedis().zscore(expiryKey, RedisTestHelper.getKey(orphan)), nullValue());
+        assertThat(RedisTestHelper.getJedis().zscore(expiryKey, RedisTestHelper.getKey(foreignOrphan)), nullValue());
+        assertThat(RedisTestHelper.getJedis().zcard(expiryKey), is(1L));
+    }
+}
@@ -0,0 +1,160 @@
+//
+// ========================================================================
+// Copyright (c) 1995 Mort Bay Consulting Pty Ltd and others.
+//
+// This program and the accompanying materials are made available under the
+// terms of the Eclipse Public License v. 2.0 which is available at
+// https://www.eclipse.org/legal/epl-2.0, or the Apache License, Version 2.0
+// which is available at https://www.apache.org/licenses/LICENSE-2.0.
+//
+// SPDX-License-Identifier: EPL-2.0 OR Apache-2.0
+// ========================================================================
+//
+
+package org.eclipse.jetty.redis.session;
+
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.TimeUnit;
+
+import org.eclipse.jetty.server.session.SessionData;
+import org.eclipse.jetty.server.session.SessionDataSerialization;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+import org.testcontainers.containers.GenericContainer;
+import org.testcontainers.containers.output.Slf4jLogConsumer;
+import redis.clients.jedis.JedisPooled;
+import redis.clients.jedis.params.SetParams;
+
+import static org.junit.jupiter.api.Assertions.assertEquals;
+import static org.junit.jupiter.api.Assertions.assertTrue;
+
+/**
+ * RedisTestHelper
+ */
+public class RedisTestHelper
+{
+    private static final Logger LOG = LoggerFactory.getLogger(RedisTestHelper.class);
+    private static final Logger REDIS_LOG = LoggerFactory.getLogger("org.eclipse.jetty.redis.session.RedisLogs");
+    private static final int REDIS_PORT = 6379;
+
+    @SuppressWarnings({"rawtypes", "unchecked"})
+    static GenericContainer redis =
+        new GenericContainer("redis:" + System.getProperty("redis.docker.version", "7.0.12"))
+            .withExposedPorts(REDIS_PORT)
+            .withLogConsumer(new Slf4jLogConsumer(REDIS_LOG));
+
+    static JedisPooled jedis;
+
+    public static JedisPooled getJedis()
+    {
+        if (!redis.isRunning())
+        {
+            try
+            {
+                long start = System.currentTimeMillis();
+                redis.start();
+                LOG.info("Redis container started for {}:{} - {}ms", redis.getHost(), redis.getMappedPort(REDIS_PORT),
+                    System.currentTimeMillis() - start);
+            }
+            catch (Exception e)
+            {
+                LOG.error(e.getMessage(), e);
+                throw new RuntimeException(e);
+            }
+        }
+        if (jedis == null)
+            jedis = new JedisPooled(redis.getHost(), redis.getMappedPort(REDIS_PORT));
+        return jedis;
+    }
+
+    public static void flushAll()
+    {
+        getJedis().flushAll();
+    }
+
+    public static void shutdown() throws Exception
+    {
+        if (jedis != null)
+            jedis.close();
+        jedis = null;
+        redis.stop();
+    }
+
+    public static RedisSessionDataStoreFactory newSessionDataStoreFactory()
+    {
+        RedisSessionDataStoreFactory factory = new RedisSessionDataStoreFactory();
+        factory.setJedis(getJedis());
+        return factory;
+    }
+
+    public static String getKey(SessionData data)
+    {
+        return RedisSessionDataStore.DEFAULT_KEY_PREFIX + data.getContextPath() + "_" + data.getVhost() + "_" + data.getId();
+    }
+
+    public static String getExpiryKey()
+    {
+        return RedisSessionDataStore.DEFAULT_KEY_PREFIX + "expiry";
+    }
+
+    /**
+     * Stores a session as the RedisSessionDataStore does, with a TTL
+     * and an entry in the sorted set of expiry times.
+     */
+    public static void createSession(SessionData data, int gracePeriodSec) throws Exception
+    {
+        createSession(data, RedisSessionData.serialize(new SessionDataSerialization(), data), gracePeriodSec);
+    }
+
+    public static void createUnreadableSession(SessionData data, int gracePeriodSec) throws Exception
+    {
+        createSession(data, "not a session".getBytes(StandardCharsets.UTF_8), gracePeriodSec);
+    }
+
+    private static void createSession(SessionData data, byte[] bytes, int gracePeriodSec)
+    {
+        String key = getKey(data);
+        SetParams params = SetParams.setParams();
+        if (data.getExpiry() > 0)
+            params.px(Math.max(1, data.getExpiry() - System.currentTimeMillis()) + TimeUnit.SECONDS.toMillis(gracePeriodSec));
+        getJedis().set(key.getBytes(StandardCharsets.UTF_8), bytes, params);
+        if (data.getExpiry() > 0)
+            getJedis().zadd(getExpiryKey(), data.getExpiry(), key);
+    }
+
+    public static boolean checkSessionExists(SessionData data)
+    {
+        return getJedis().exists(getKey(data));
+    }
+
+    public static boolean checkSessionPersisted(SessionData data) throws Exception
+    {
+        byte[] bytes = getJedis().get(getKey(data).getBytes(StandardCharsets.UTF_8));
+        if (bytes == null)
+            return false;
+
+        SessionData saved = RedisSessionData.deserialize(new SessionDataSerialization(), bytes);
+
+        assertEquals(data.getId(), saved.getId());
+        assertEquals(data.getContextPath(), saved.getContextPath());
+        assertEquals(data.getVhost(), saved.getVhost());
+        assertEquals(data.getLastNode(), saved.getLastNode());
+        assertEquals(data.getCreated(), saved.getCreated());
+        assertEquals(data.getAccessed(), saved.getAccessed());
+        assertEquals(data.getLastAccessed(), saved.getLastAccessed());
+        assertEquals(data.getCookieSet(), saved.getCookieSet());
+        assertEquals(data.getExpiry(), saved.getExpiry());
+        assertEquals(data.getMaxInactiveMs(), saved.getMaxInactiveMs());
+
+        //same number of attributes
+        assertEquals(data.getAllAttributes().size(), saved.getAllAttributes().size());
+        //same keys
+        assertTrue(data.getKeys().equals(saved.getKeys()));
+        //same values
+        for (String name : data.getKeys())
+        {
+            assertTrue(data.getAttribute(name).equals(saved.getAttribute(name)));
+        }
+        return true;
+    }
+}